@@ -0,0 +1,19 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package analyticsrollup
+
+import (
+	"github.com/mattermost/mattermost-server/app"
+	tjobs "github.com/mattermost/mattermost-server/einterfaces/jobs"
+)
+
+type AnalyticsRollupJobInterfaceImpl struct {
+	App *app.App
+}
+
+func init() {
+	app.RegisterJobsAnalyticsRollupJobInterface(func(a *app.App) tjobs.AnalyticsRollupJobInterface {
+		return &AnalyticsRollupJobInterfaceImpl{a}
+	})
+}
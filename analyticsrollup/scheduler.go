@@ -0,0 +1,61 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package analyticsrollup
+
+import (
+	"time"
+
+	"github.com/mattermost/mattermost-server/app"
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+type Scheduler struct {
+	App *app.App
+}
+
+func (m *AnalyticsRollupJobInterfaceImpl) MakeScheduler() model.Scheduler {
+	return &Scheduler{m.App}
+}
+
+func (scheduler *Scheduler) Name() string {
+	return "AnalyticsRollupScheduler"
+}
+
+func (scheduler *Scheduler) JobType() string {
+	return model.JOB_TYPE_ANALYTICS_ROLLUP
+}
+
+func (scheduler *Scheduler) Enabled(cfg *model.Config) bool {
+	return true
+}
+
+// NextScheduleTime is the midnight after the last successful rollup, or now if there hasn't been one.
+func (scheduler *Scheduler) NextScheduleTime(cfg *model.Config, now time.Time, pendingJobs bool, lastSuccessfulJob *model.Job) *time.Time {
+	if lastSuccessfulJob == nil {
+		return &now
+	}
+
+	lastTime := time.Unix(0, lastSuccessfulJob.LastActivityAt*int64(time.Millisecond))
+	nextTime := utils.StartOfDay(lastTime.AddDate(0, 0, 1))
+	if nextTime.Before(now) {
+		return &now
+	}
+
+	return &nextTime
+}
+
+func (scheduler *Scheduler) ScheduleJob(cfg *model.Config, pendingJobs bool, lastSuccessfulJob *model.Job) (*model.Job, *model.AppError) {
+	if pendingJobs {
+		return nil, nil
+	}
+
+	data := map[string]string{}
+
+	if job, err := scheduler.App.Jobs.CreateJob(model.JOB_TYPE_ANALYTICS_ROLLUP, data); err != nil {
+		return nil, err
+	} else {
+		return job, nil
+	}
+}
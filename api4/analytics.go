@@ -0,0 +1,143 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"encoding/csv"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// USER_ANALYTICS_CSV_PAGE_SIZE is how many users are read at a time when exporting the activity of every user.
+const USER_ANALYTICS_CSV_PAGE_SIZE = 1000
+
+func (api *API) InitAnalytics() {
+	api.BaseRoutes.ApiRoot.Handle("/analytics/teams/{team_id:[A-Za-z0-9]+}", api.ApiSessionRequired(getTeamAnalyticsSeries)).Methods("GET")
+	api.BaseRoutes.ApiRoot.Handle("/analytics/channels/{channel_id:[A-Za-z0-9]+}", api.ApiSessionRequired(getChannelAnalyticsSeries)).Methods("GET")
+	api.BaseRoutes.ApiRoot.Handle("/analytics/users", api.ApiSessionRequired(getUserAnalytics)).Methods("GET")
+	api.BaseRoutes.ApiRoot.Handle("/analytics/seats", api.ApiPermissionRequired(model.PERMISSION_READ_SYSTEM_CONSOLE, getSeatReport)).Methods("GET")
+}
+
+func getTeamAnalyticsSeries(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireTeamId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_MANAGE_TEAM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_TEAM)
+		return
+	}
+
+	writeAnalyticsSeries(c, w, r, c.Params.TeamId, "")
+}
+
+func getChannelAnalyticsSeries(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireChannelId()
+	if c.Err != nil {
+		return
+	}
+
+	channel, err := c.App.GetChannel(c.Params.ChannelId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	if !c.App.SessionHasPermissionToTeam(c.Session, channel.TeamId, model.PERMISSION_MANAGE_TEAM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_TEAM)
+		return
+	}
+
+	writeAnalyticsSeries(c, w, r, channel.TeamId, channel.Id)
+}
+
+func getUserAnalytics(c *Context, w http.ResponseWriter, r *http.Request) {
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	query := r.URL.Query()
+	from := query.Get("from")
+	to := query.Get("to")
+
+	switch query.Get("format") {
+	case "":
+		activity, err := c.App.GetUserAnalytics(from, to, c.Params.Page, c.Params.PerPage)
+		if err != nil {
+			c.Err = err
+			return
+		}
+
+		w.Write([]byte(model.AnalyticsUserActivityListToJson(activity)))
+	case "csv":
+		writeUserAnalyticsCsv(c, w, from, to)
+	default:
+		c.SetInvalidParam("format")
+	}
+}
+
+// writeUserAnalyticsCsv writes the activity of every user rather than a page of it, since the export is meant
+// to be read in a spreadsheet.
+func writeUserAnalyticsCsv(c *Context, w http.ResponseWriter, from string, to string) {
+	activity, err := c.App.GetUserAnalytics(from, to, 0, USER_ANALYTICS_CSV_PAGE_SIZE)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", "attachment;filename=\"user_analytics.csv\"")
+
+	writer := csv.NewWriter(w)
+	writer.Write(model.AnalyticsUserActivityCsvHeader())
+
+	for page := 1; len(activity) > 0; page++ {
+		for _, user := range activity {
+			writer.Write(user.CsvRow())
+		}
+
+		if len(activity) < USER_ANALYTICS_CSV_PAGE_SIZE {
+			break
+		}
+
+		// the response has already started, so an error can only cut the export short
+		if activity, err = c.App.GetUserAnalytics(from, to, page, USER_ANALYTICS_CSV_PAGE_SIZE); err != nil {
+			mlog.Error("Failed to export the user analytics", mlog.String("error", err.Error()))
+			break
+		}
+	}
+
+	writer.Flush()
+}
+
+func getSeatReport(c *Context, w http.ResponseWriter, r *http.Request) {
+	report, err := c.App.GetSeatReport()
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(report.ToJson()))
+}
+
+func writeAnalyticsSeries(c *Context, w http.ResponseWriter, r *http.Request, teamId string, channelId string) {
+	query := r.URL.Query()
+
+	metric := query.Get("metric")
+	if metric == "" {
+		metric = model.ANALYTICS_METRIC_POSTS
+	}
+
+	rows, err := c.App.GetAnalyticsSeries(teamId, channelId, metric, query.Get("from"), query.Get("to"))
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(rows.ToJson()))
+}
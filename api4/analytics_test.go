@@ -0,0 +1,178 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+func TestGetAnalyticsSeries(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	yesterday := utils.StartOfDay(utils.Yesterday())
+	twoDaysAgo := yesterday.AddDate(0, 0, -1)
+
+	for _, createAt := range []int64{
+		utils.MillisFromTime(twoDaysAgo) + 1000,
+		utils.MillisFromTime(yesterday) + 1000,
+		utils.MillisFromTime(yesterday) + 2000,
+	} {
+		store.Must(th.App.Srv.Store.Post().Save(&model.Post{UserId: th.BasicUser.Id, ChannelId: th.BasicChannel.Id, Message: "message", CreateAt: createAt}))
+	}
+
+	if err := th.App.RollupAnalyticsDay(twoDaysAgo); err != nil {
+		t.Fatal(err)
+	}
+	if err := th.App.RollupAnalyticsDay(yesterday); err != nil {
+		t.Fatal(err)
+	}
+
+	from := twoDaysAgo.Format(model.ANALYTICS_DAY_FORMAT)
+	to := yesterday.Format(model.ANALYTICS_DAY_FORMAT)
+
+	_, resp := Client.GetTeamAnalyticsSeries(th.BasicTeam.Id, model.ANALYTICS_METRIC_POSTS, from, to)
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = Client.GetChannelAnalyticsSeries(th.BasicChannel.Id, model.ANALYTICS_METRIC_POSTS, from, to)
+	CheckForbiddenStatus(t, resp)
+
+	rows, resp := th.SystemAdminClient.GetTeamAnalyticsSeries(th.BasicTeam.Id, model.ANALYTICS_METRIC_POSTS, from, to)
+	CheckNoError(t, resp)
+	if len(rows) != 2 || rows[0].Name != from || rows[0].Value != 1 || rows[1].Name != to || rows[1].Value != 2 {
+		t.Fatal("wrong team series", rows.ToJson())
+	}
+
+	rows, resp = th.SystemAdminClient.GetChannelAnalyticsSeries(th.BasicChannel.Id, model.ANALYTICS_METRIC_ACTIVE_USERS, from, to)
+	CheckNoError(t, resp)
+	if len(rows) != 2 || rows[0].Value != 1 || rows[1].Value != 1 {
+		t.Fatal("wrong channel series", rows.ToJson())
+	}
+
+	// team admins can see the series of their team
+	th.UpdateUserToTeamAdmin(th.BasicUser, th.BasicTeam)
+	th.App.InvalidateAllCaches()
+
+	rows, resp = Client.GetTeamAnalyticsSeries(th.BasicTeam.Id, "", from, to)
+	CheckNoError(t, resp)
+	if len(rows) != 2 || rows[1].Value != 2 {
+		t.Fatal("should default to the posts metric", rows.ToJson())
+	}
+
+	_, resp = Client.GetTeamAnalyticsSeries(th.BasicTeam.Id, "junk", from, to)
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = Client.GetTeamAnalyticsSeries(th.BasicTeam.Id, model.ANALYTICS_METRIC_POSTS, "junk", to)
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = Client.GetChannelAnalyticsSeries(model.NewId(), model.ANALYTICS_METRIC_POSTS, from, to)
+	CheckNotFoundStatus(t, resp)
+
+	Client.Logout()
+	_, resp = Client.GetTeamAnalyticsSeries(th.BasicTeam.Id, model.ANALYTICS_METRIC_POSTS, from, to)
+	CheckUnauthorizedStatus(t, resp)
+}
+
+func TestGetUserAnalytics(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	yesterday := utils.StartOfDay(utils.Yesterday())
+	for _, createAt := range []int64{
+		utils.MillisFromTime(yesterday) + 1000,
+		utils.MillisFromTime(yesterday) + 2000,
+	} {
+		store.Must(th.App.Srv.Store.Post().Save(&model.Post{UserId: th.BasicUser.Id, ChannelId: th.BasicChannel.Id, Message: "message", CreateAt: createAt}))
+	}
+
+	require.Nil(t, th.App.RollupAnalyticsDay(yesterday))
+
+	day := yesterday.Format(model.ANALYTICS_DAY_FORMAT)
+
+	_, resp := Client.GetUserAnalytics(day, day, 0, 100)
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = Client.GetUserAnalyticsCsv(day, day)
+	CheckForbiddenStatus(t, resp)
+
+	activity, resp := th.SystemAdminClient.GetUserAnalytics(day, day, 0, 100)
+	CheckNoError(t, resp)
+	var found *model.AnalyticsUserActivity
+	for _, user := range activity {
+		if user.UserId == th.BasicUser.Id {
+			found = user
+		}
+	}
+	require.NotNil(t, found)
+	assert.Equal(t, th.BasicUser.Username, found.Username)
+	assert.Equal(t, int64(2), found.Posts)
+
+	data, resp := th.SystemAdminClient.GetUserAnalyticsCsv(day, day)
+	CheckNoError(t, resp)
+	assert.Equal(t, "text/csv", resp.Header.Get("Content-Type"))
+
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	require.Nil(t, err)
+	require.Len(t, records, len(activity)+1)
+	assert.Equal(t, model.AnalyticsUserActivityCsvHeader(), records[0])
+	assert.Contains(t, records, found.CsvRow())
+
+	_, resp = th.SystemAdminClient.GetUserAnalytics("junk", day, 0, 100)
+	CheckBadRequestStatus(t, resp)
+
+	r, appErr := th.SystemAdminClient.DoApiGet(th.SystemAdminClient.GetAnalyticsRoute()+"/users?format=junk", "")
+	require.NotNil(t, appErr)
+	assert.Equal(t, http.StatusBadRequest, r.StatusCode)
+
+	Client.Logout()
+	_, resp = Client.GetUserAnalytics(day, day, 0, 100)
+	CheckUnauthorizedStatus(t, resp)
+}
+
+func TestGetSeatReport(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+
+	_, resp := th.Client.GetSeatReport()
+	CheckForbiddenStatus(t, resp)
+
+	report, resp := th.SystemAdminClient.GetSeatReport()
+	CheckNoError(t, resp)
+
+	if report.ActiveUserCount == 0 {
+		t.Fatal("should have counted the active users")
+	}
+
+	found := false
+	for _, team := range report.Teams {
+		if team.TeamId == th.BasicTeam.Id {
+			found = true
+
+			stats, resp := th.SystemAdminClient.GetTeamStats(th.BasicTeam.Id, "")
+			CheckNoError(t, resp)
+			if team.ActiveMemberCount != stats.ActiveMemberCount {
+				t.Fatal("wrong member count for the team")
+			}
+		}
+	}
+	if !found {
+		t.Fatal("should have the basic team")
+	}
+
+	th.Client.Logout()
+	_, resp = th.Client.GetSeatReport()
+	CheckUnauthorizedStatus(t, resp)
+}
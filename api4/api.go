@@ -23,6 +23,9 @@ type Routes struct {
 	UserByUsername *mux.Router // 'api/v4/users/username/{username:[A-Za-z0-9_-\.]+}'
 	UserByEmail    *mux.Router // 'api/v4/users/email/{email}'
 
+	UserAttributeFields *mux.Router // 'api/v4/users/attributes/fields'
+	UserAttributeField  *mux.Router // 'api/v4/users/attributes/fields/{field_id:[A-Za-z0-9]+}'
+
 	Teams              *mux.Router // 'api/v4/teams'
 	TeamsForUser       *mux.Router // 'api/v4/users/{user_id:[A-Za-z0-9]+}/teams'
 	Team               *mux.Router // 'api/v4/teams/{team_id:[A-Za-z0-9]+}'
@@ -39,8 +42,12 @@ type Routes struct {
 	ChannelByNameForTeamName *mux.Router // 'api/v4/teams/name/{team_name:[A-Za-z0-9_-]+}/channels/name/{channel_name:[A-Za-z0-9_-]+}'
 	ChannelsForTeam          *mux.Router // 'api/v4/teams/{team_id:[A-Za-z0-9]+}/channels'
 	ChannelMembers           *mux.Router // 'api/v4/channels/{channel_id:[A-Za-z0-9]+}/members'
+	ChannelMemberHistory     *mux.Router // 'api/v4/channels/{channel_id:[A-Za-z0-9]+}/members/history'
 	ChannelMember            *mux.Router // 'api/v4/channels/{channel_id:[A-Za-z0-9]+}/members/{user_id:[A-Za-z0-9]+}'
 	ChannelMembersForUser    *mux.Router // 'api/v4/users/{user_id:[A-Za-z0-9]+}/teams/{team_id:[A-Za-z0-9]+}/channels/members'
+	ChannelCategories        *mux.Router // 'api/v4/users/{user_id:[A-Za-z0-9]+}/teams/{team_id:[A-Za-z0-9]+}/channels/categories'
+	ThreadsForUser           *mux.Router // 'api/v4/users/{user_id:[A-Za-z0-9]+}/teams/{team_id:[A-Za-z0-9]+}/threads'
+	ThreadForUser            *mux.Router // 'api/v4/users/{user_id:[A-Za-z0-9]+}/teams/{team_id:[A-Za-z0-9]+}/threads/{thread_id:[A-Za-z0-9]+}'
 
 	Posts           *mux.Router // 'api/v4/posts'
 	Post            *mux.Router // 'api/v4/posts/{post_id:[A-Za-z0-9]+}'
@@ -48,8 +55,10 @@ type Routes struct {
 	PostsForUser    *mux.Router // 'api/v4/users/{user_id:[A-Za-z0-9]+}/posts'
 	PostForUser     *mux.Router // 'api/v4/users/{user_id:[A-Za-z0-9]+}/posts/{post_id:[A-Za-z0-9]+}'
 
-	Files *mux.Router // 'api/v4/files'
-	File  *mux.Router // 'api/v4/files/{file_id:[A-Za-z0-9]+}'
+	Files     *mux.Router // 'api/v4/files'
+	FileLinks *mux.Router // 'api/v4/files/links'
+	FileUsage *mux.Router // 'api/v4/files/usage'
+	File      *mux.Router // 'api/v4/files/{file_id:[A-Za-z0-9]+}'
 
 	Plugins *mux.Router // 'api/v4/plugins'
 	Plugin  *mux.Router // 'api/v4/plugins/{plugin_id:[A-Za-z0-9_-]+}'
@@ -83,6 +92,8 @@ type Routes struct {
 
 	DataRetention *mux.Router // 'api/v4/data_retention'
 
+	TermsOfService *mux.Router // 'api/v4/terms_of_service'
+
 	Brand *mux.Router // 'api/v4/brand'
 
 	System *mux.Router // 'api/v4/system'
@@ -91,6 +102,8 @@ type Routes struct {
 
 	Preferences *mux.Router // 'api/v4/users/{user_id:[A-Za-z0-9]+}/preferences'
 
+	SavedSearches *mux.Router // 'api/v4/users/{user_id:[A-Za-z0-9]+}/saved_searches'
+
 	License *mux.Router // 'api/v4/license'
 
 	Public *mux.Router // 'api/v4/public'
@@ -99,6 +112,17 @@ type Routes struct {
 
 	Roles *mux.Router // 'api/v4/roles'
 
+	CustomGroups *mux.Router // 'api/v4/groups/custom'
+	CustomGroup  *mux.Router // 'api/v4/groups/custom/{group_id:[A-Za-z0-9]+}'
+
+	ProvisioningTokens *mux.Router // 'api/v4/provisioning_tokens'
+	ProvisioningToken  *mux.Router // 'api/v4/provisioning_tokens/{token_id:[A-Za-z0-9]+}'
+
+	TeamTemplates *mux.Router // 'api/v4/team_templates'
+	TeamTemplate  *mux.Router // 'api/v4/team_templates/{template_id:[A-Za-z0-9]+}'
+
+	Scim *mux.Router // 'scim/v2'
+
 	Emojis      *mux.Router // 'api/v4/emoji'
 	Emoji       *mux.Router // 'api/v4/emoji/{emoji_id:[A-Za-z0-9]+}'
 	EmojiByName *mux.Router // 'api/v4/emoji/name/{emoji_name:[A-Za-z0-9_-\.]+}'
@@ -111,12 +135,30 @@ type Routes struct {
 type API struct {
 	App        *app.App
 	BaseRoutes *Routes
+
+	// local is set when the routes are served over the local mode socket.
+	local bool
 }
 
 func Init(a *app.App, root *mux.Router) *API {
+	api := initRoutes(a, root, false)
+
+	a.InitEmailBatching()
+
+	return api
+}
+
+// InitLocal adds the API routes to the router that's served over the local mode socket, where requests are made as a
+// system admin without a session.
+func InitLocal(a *app.App, root *mux.Router) *API {
+	return initRoutes(a, root, true)
+}
+
+func initRoutes(a *app.App, root *mux.Router, local bool) *API {
 	api := &API{
 		App:        a,
 		BaseRoutes: &Routes{},
+		local:      local,
 	}
 
 	api.BaseRoutes.Root = root
@@ -127,6 +169,9 @@ func Init(a *app.App, root *mux.Router) *API {
 	api.BaseRoutes.UserByUsername = api.BaseRoutes.Users.PathPrefix("/username/{username:[A-Za-z0-9\\_\\-\\.]+}").Subrouter()
 	api.BaseRoutes.UserByEmail = api.BaseRoutes.Users.PathPrefix("/email/{email}").Subrouter()
 
+	api.BaseRoutes.UserAttributeFields = api.BaseRoutes.Users.PathPrefix("/attributes/fields").Subrouter()
+	api.BaseRoutes.UserAttributeField = api.BaseRoutes.UserAttributeFields.PathPrefix("/{field_id:[A-Za-z0-9]+}").Subrouter()
+
 	api.BaseRoutes.Teams = api.BaseRoutes.ApiRoot.PathPrefix("/teams").Subrouter()
 	api.BaseRoutes.TeamsForUser = api.BaseRoutes.User.PathPrefix("/teams").Subrouter()
 	api.BaseRoutes.Team = api.BaseRoutes.Teams.PathPrefix("/{team_id:[A-Za-z0-9]+}").Subrouter()
@@ -143,8 +188,13 @@ func Init(a *app.App, root *mux.Router) *API {
 	api.BaseRoutes.ChannelByNameForTeamName = api.BaseRoutes.TeamByName.PathPrefix("/channels/name/{channel_name:[A-Za-z0-9_-]+}").Subrouter()
 	api.BaseRoutes.ChannelsForTeam = api.BaseRoutes.Team.PathPrefix("/channels").Subrouter()
 	api.BaseRoutes.ChannelMembers = api.BaseRoutes.Channel.PathPrefix("/members").Subrouter()
+	// registered before ChannelMember so that "history" isn't taken for a user id
+	api.BaseRoutes.ChannelMemberHistory = api.BaseRoutes.ChannelMembers.PathPrefix("/history").Subrouter()
 	api.BaseRoutes.ChannelMember = api.BaseRoutes.ChannelMembers.PathPrefix("/{user_id:[A-Za-z0-9]+}").Subrouter()
 	api.BaseRoutes.ChannelMembersForUser = api.BaseRoutes.User.PathPrefix("/teams/{team_id:[A-Za-z0-9]+}/channels/members").Subrouter()
+	api.BaseRoutes.ChannelCategories = api.BaseRoutes.User.PathPrefix("/teams/{team_id:[A-Za-z0-9]+}/channels/categories").Subrouter()
+	api.BaseRoutes.ThreadsForUser = api.BaseRoutes.TeamForUser.PathPrefix("/threads").Subrouter()
+	api.BaseRoutes.ThreadForUser = api.BaseRoutes.ThreadsForUser.PathPrefix("/{thread_id:[A-Za-z0-9]+}").Subrouter()
 
 	api.BaseRoutes.Posts = api.BaseRoutes.ApiRoot.PathPrefix("/posts").Subrouter()
 	api.BaseRoutes.Post = api.BaseRoutes.Posts.PathPrefix("/{post_id:[A-Za-z0-9]+}").Subrouter()
@@ -153,6 +203,8 @@ func Init(a *app.App, root *mux.Router) *API {
 	api.BaseRoutes.PostForUser = api.BaseRoutes.PostsForUser.PathPrefix("/{post_id:[A-Za-z0-9]+}").Subrouter()
 
 	api.BaseRoutes.Files = api.BaseRoutes.ApiRoot.PathPrefix("/files").Subrouter()
+	api.BaseRoutes.FileLinks = api.BaseRoutes.Files.PathPrefix("/links").Subrouter()
+	api.BaseRoutes.FileUsage = api.BaseRoutes.Files.PathPrefix("/usage").Subrouter()
 	api.BaseRoutes.File = api.BaseRoutes.Files.PathPrefix("/{file_id:[A-Za-z0-9]+}").Subrouter()
 	api.BaseRoutes.PublicFile = api.BaseRoutes.Root.PathPrefix("/files/{file_id:[A-Za-z0-9]+}/public").Subrouter()
 
@@ -180,12 +232,14 @@ func Init(a *app.App, root *mux.Router) *API {
 	api.BaseRoutes.Brand = api.BaseRoutes.ApiRoot.PathPrefix("/brand").Subrouter()
 	api.BaseRoutes.System = api.BaseRoutes.ApiRoot.PathPrefix("/system").Subrouter()
 	api.BaseRoutes.Preferences = api.BaseRoutes.User.PathPrefix("/preferences").Subrouter()
+	api.BaseRoutes.SavedSearches = api.BaseRoutes.User.PathPrefix("/saved_searches").Subrouter()
 	api.BaseRoutes.License = api.BaseRoutes.ApiRoot.PathPrefix("/license").Subrouter()
 	api.BaseRoutes.Public = api.BaseRoutes.ApiRoot.PathPrefix("/public").Subrouter()
 	api.BaseRoutes.Reactions = api.BaseRoutes.ApiRoot.PathPrefix("/reactions").Subrouter()
 	api.BaseRoutes.Jobs = api.BaseRoutes.ApiRoot.PathPrefix("/jobs").Subrouter()
 	api.BaseRoutes.Elasticsearch = api.BaseRoutes.ApiRoot.PathPrefix("/elasticsearch").Subrouter()
 	api.BaseRoutes.DataRetention = api.BaseRoutes.ApiRoot.PathPrefix("/data_retention").Subrouter()
+	api.BaseRoutes.TermsOfService = api.BaseRoutes.ApiRoot.PathPrefix("/terms_of_service").Subrouter()
 
 	api.BaseRoutes.Emojis = api.BaseRoutes.ApiRoot.PathPrefix("/emoji").Subrouter()
 	api.BaseRoutes.Emoji = api.BaseRoutes.ApiRoot.PathPrefix("/emoji/{emoji_id:[A-Za-z0-9]+}").Subrouter()
@@ -199,23 +253,42 @@ func Init(a *app.App, root *mux.Router) *API {
 
 	api.BaseRoutes.Roles = api.BaseRoutes.ApiRoot.PathPrefix("/roles").Subrouter()
 
+	api.BaseRoutes.CustomGroups = api.BaseRoutes.ApiRoot.PathPrefix("/groups/custom").Subrouter()
+	api.BaseRoutes.CustomGroup = api.BaseRoutes.CustomGroups.PathPrefix("/{group_id:[A-Za-z0-9]+}").Subrouter()
+
+	api.BaseRoutes.ProvisioningTokens = api.BaseRoutes.ApiRoot.PathPrefix("/provisioning_tokens").Subrouter()
+	api.BaseRoutes.ProvisioningToken = api.BaseRoutes.ProvisioningTokens.PathPrefix("/{token_id:[A-Za-z0-9]+}").Subrouter()
+
+	api.BaseRoutes.TeamTemplates = api.BaseRoutes.ApiRoot.PathPrefix("/team_templates").Subrouter()
+	api.BaseRoutes.TeamTemplate = api.BaseRoutes.TeamTemplates.PathPrefix("/{template_id:[A-Za-z0-9]+}").Subrouter()
+
+	api.BaseRoutes.Scim = api.BaseRoutes.Root.PathPrefix(model.SCIM_URL_SUFFIX).Subrouter()
+
 	api.BaseRoutes.Image = api.BaseRoutes.ApiRoot.PathPrefix("/image").Subrouter()
 
 	api.InitUser()
 	api.InitTeam()
 	api.InitChannel()
+	api.InitChannelCategory()
+	api.InitChannelModeration()
+	api.InitChannelNameHistory()
 	api.InitPost()
+	api.InitThread()
 	api.InitFile()
 	api.InitSystem()
 	api.InitWebhook()
 	api.InitPreference()
+	api.InitSavedSearch()
 	api.InitSaml()
 	api.InitCompliance()
 	api.InitCluster()
 	api.InitLdap()
 	api.InitElasticsearch()
 	api.InitDataRetention()
+	api.InitTermsOfService()
+	api.InitAnalytics()
 	api.InitBrand()
+	api.InitTeamBranding()
 	api.InitJob()
 	api.InitCommand()
 	api.InitStatus()
@@ -227,12 +300,16 @@ func Init(a *app.App, root *mux.Router) *API {
 	api.InitOpenGraph()
 	api.InitPlugin()
 	api.InitRole()
+	api.InitCustomGroup()
+	api.InitUserAttribute()
+	api.InitProvisioningToken()
+	api.InitTeamTemplate()
+	api.InitScim()
 	api.InitImage()
+	api.InitShortPermalink()
 
 	root.Handle("/api/v4/{anything:.*}", http.HandlerFunc(api.Handle404))
 
-	a.InitEmailBatching()
-
 	return api
 }
 
@@ -5,12 +5,26 @@ package api4
 
 import (
 	"flag"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http/httptest"
 	"os"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
 	"testing"
 
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/store/storetest"
 	"github.com/mattermost/mattermost-server/utils"
+	"github.com/mattermost/mattermost-server/web"
 )
 
 func TestMain(m *testing.M) {
@@ -50,3 +64,110 @@ func TestMain(m *testing.M) {
 
 	status = m.Run()
 }
+
+func TestCorsExcludesSystemAdminRoutes(t *testing.T) {
+	th := Setup()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.ServiceSettings.AllowCorsFrom = "*"
+	})
+
+	systemAdminHandlers := findSystemAdminHandlers(t)
+	require.NotEmpty(t, systemAdminHandlers)
+
+	routeVariable := regexp.MustCompile(`\{[^}]*\}`)
+	checked := 0
+
+	err := th.App.Srv.Router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		handler, ok := route.GetHandler().(*web.Handler)
+		if !ok {
+			return nil
+		}
+
+		name := runtime.FuncForPC(reflect.ValueOf(handler.HandleFunc).Pointer()).Name()
+		name = name[strings.LastIndex(name, ".")+1:]
+		if handler.RequirePermission == nil && !systemAdminHandlers[name] {
+			return nil
+		}
+
+		template, err := route.GetPathTemplate()
+		require.Nil(t, err)
+
+		r := httptest.NewRequest("OPTIONS", routeVariable.ReplaceAllString(template, model.NewId()), nil)
+		r.Header.Set("Origin", "https://example.com")
+		r.Header.Set("Access-Control-Request-Method", "POST")
+
+		w := httptest.NewRecorder()
+		th.App.Srv.Server.Handler.ServeHTTP(w, r)
+
+		assert.Equal(t, "", w.Header().Get("Access-Control-Allow-Origin"), "%v (%v) should be excluded from CORS", template, name)
+		checked++
+
+		return nil
+	})
+	require.Nil(t, err)
+	require.NotZero(t, checked)
+}
+
+// findSystemAdminHandlers returns the names of the API handlers that refuse any session without
+// PERMISSION_MANAGE_SYSTEM before doing anything else.
+func findSystemAdminHandlers(t *testing.T) map[string]bool {
+	packages, err := parser.ParseDir(token.NewFileSet(), ".", func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	require.Nil(t, err)
+
+	handlers := map[string]bool{}
+	for _, file := range packages["api4"].Files {
+		for _, decl := range file.Decls {
+			function, ok := decl.(*ast.FuncDecl)
+			if !ok || function.Body == nil {
+				continue
+			}
+
+			for _, stmt := range function.Body.List {
+				if ifStmt, ok := stmt.(*ast.IfStmt); ok && isManageSystemPermissionCheck(ifStmt) {
+					handlers[function.Name.Name] = true
+				}
+			}
+		}
+	}
+
+	return handlers
+}
+
+// isManageSystemPermissionCheck returns whether the statement is
+//
+//	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+//		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+//		...
+func isManageSystemPermissionCheck(ifStmt *ast.IfStmt) bool {
+	not, ok := ifStmt.Cond.(*ast.UnaryExpr)
+	if !ok || not.Op != token.NOT || !isManageSystemCall(not.X, "SessionHasPermissionTo") {
+		return false
+	}
+
+	if len(ifStmt.Body.List) == 0 {
+		return false
+	}
+
+	setError, ok := ifStmt.Body.List[0].(*ast.ExprStmt)
+	return ok && isManageSystemCall(setError.X, "SetPermissionError")
+}
+
+// isManageSystemCall returns whether the expression is a call to the named method with
+// model.PERMISSION_MANAGE_SYSTEM as its last argument.
+func isManageSystemCall(expr ast.Expr, method string) bool {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || len(call.Args) == 0 {
+		return false
+	}
+
+	if fun, ok := call.Fun.(*ast.SelectorExpr); !ok || fun.Sel.Name != method {
+		return false
+	}
+
+	permission, ok := call.Args[len(call.Args)-1].(*ast.SelectorExpr)
+	return ok && permission.Sel.Name == "PERMISSION_MANAGE_SYSTEM"
+}
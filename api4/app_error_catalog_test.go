@@ -0,0 +1,253 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+var updateAppErrorCatalog = flag.Bool("update-app-error-catalog", false, "add the AppErrors that aren't registered yet to the catalog")
+
+// appErrorCatalogSources are the files that can only construct AppErrors with registered ids. The errors that every
+// handler can return are checked along with those from the handlers themselves.
+var appErrorCatalogSources = []string{
+	"user.go",
+	"channel.go",
+	"post.go",
+	"../web/context.go",
+	"../web/handlers.go",
+}
+
+const appErrorCatalogFile = "../model/app_error_catalog_entries.go"
+
+const appErrorCatalogHeader = `// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import "net/http"
+
+// appErrorCatalog is generated by running "make app-error-catalog", which adds any AppErrors constructed in the files
+// that TestAppErrorCatalog checks that aren't registered yet. Entries can also be added by hand, but the codes of
+// existing ones must never be changed since clients depend on them.
+var appErrorCatalog = []*AppErrorCatalogEntry{
+`
+
+// httpStatuses are the statuses that AppErrors can be constructed with, by the name of their constants in net/http.
+var httpStatuses = map[string]int{
+	"StatusFound":                 http.StatusFound,
+	"StatusTemporaryRedirect":     http.StatusTemporaryRedirect,
+	"StatusBadRequest":            http.StatusBadRequest,
+	"StatusUnauthorized":          http.StatusUnauthorized,
+	"StatusForbidden":             http.StatusForbidden,
+	"StatusNotFound":              http.StatusNotFound,
+	"StatusMethodNotAllowed":      http.StatusMethodNotAllowed,
+	"StatusNotAcceptable":         http.StatusNotAcceptable,
+	"StatusConflict":              http.StatusConflict,
+	"StatusGone":                  http.StatusGone,
+	"StatusPreconditionFailed":    http.StatusPreconditionFailed,
+	"StatusRequestEntityTooLarge": http.StatusRequestEntityTooLarge,
+	"StatusUnprocessableEntity":   http.StatusUnprocessableEntity,
+	"StatusTooManyRequests":       http.StatusTooManyRequests,
+	"StatusInternalServerError":   http.StatusInternalServerError,
+	"StatusNotImplemented":        http.StatusNotImplemented,
+	"StatusServiceUnavailable":    http.StatusServiceUnavailable,
+}
+
+// constructedAppError is a call to model.NewAppError with an id that's a string literal. Its status is 0 if it isn't
+// one of the constants in net/http.
+type constructedAppError struct {
+	Id       string
+	Status   int
+	Position string
+}
+
+func findConstructedAppErrors(t *testing.T, filename string, src interface{}) []*constructedAppError {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, 0)
+	require.Nil(t, err)
+
+	var constructed []*constructedAppError
+	ast.Inspect(file, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok || len(call.Args) != 5 {
+			return true
+		}
+
+		if fun, ok := call.Fun.(*ast.SelectorExpr); !ok || fun.Sel.Name != "NewAppError" {
+			return true
+		}
+
+		idArg, ok := call.Args[1].(*ast.BasicLit)
+		if !ok || idArg.Kind != token.STRING {
+			return true
+		}
+
+		id, err := strconv.Unquote(idArg.Value)
+		require.Nil(t, err)
+
+		status := 0
+		if statusArg, ok := call.Args[4].(*ast.SelectorExpr); ok {
+			if pkg, ok := statusArg.X.(*ast.Ident); ok && pkg.Name == "http" {
+				status = httpStatuses[statusArg.Sel.Name]
+			}
+		}
+
+		constructed = append(constructed, &constructedAppError{
+			Id:       id,
+			Status:   status,
+			Position: fset.Position(call.Pos()).String(),
+		})
+		return true
+	})
+
+	return constructed
+}
+
+// unregisteredAppErrors returns the AppErrors that are constructed with ids that aren't in the catalog.
+func unregisteredAppErrors(constructed []*constructedAppError) []*constructedAppError {
+	var unregistered []*constructedAppError
+	for _, appErr := range constructed {
+		if model.GetAppErrorCatalogEntry(appErr.Id) == nil {
+			unregistered = append(unregistered, appErr)
+		}
+	}
+
+	return unregistered
+}
+
+func TestAppErrorCatalog(t *testing.T) {
+	var constructed []*constructedAppError
+	for _, filename := range appErrorCatalogSources {
+		constructed = append(constructed, findConstructedAppErrors(t, filename, nil)...)
+	}
+	require.NotEmpty(t, constructed)
+
+	if *updateAppErrorCatalog {
+		writeAppErrorCatalog(t, unregisteredAppErrors(constructed))
+		return
+	}
+
+	for _, appErr := range unregisteredAppErrors(constructed) {
+		t.Errorf("%v: %v isn't in the AppError catalog, run this test with -update-app-error-catalog to add it", appErr.Position, appErr.Id)
+	}
+
+	for _, appErr := range constructed {
+		if entry := model.GetAppErrorCatalogEntry(appErr.Id); entry != nil && appErr.Status != 0 && appErr.Status != entry.StatusCode {
+			t.Errorf("%v: %v is constructed with status %v but registered with %v", appErr.Position, appErr.Id, appErr.Status, entry.StatusCode)
+		}
+	}
+
+	codes := map[string]string{}
+	for _, entry := range model.GetAppErrorCatalog() {
+		assert.Contains(t, entry.Code, ".", "%v should have a code that can't be mistaken for the generic ones", entry.Id)
+		if id, ok := codes[entry.Code]; ok {
+			t.Errorf("%v and %v have the same code %v", id, entry.Id, entry.Code)
+		}
+		codes[entry.Code] = entry.Id
+	}
+}
+
+func TestUnregisteredAppErrors(t *testing.T) {
+	src := `package api4
+
+func handler(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.Err = model.NewAppError("handler", "api.context.invalid_url_param.app_error", nil, "", http.StatusBadRequest)
+	c.Err = model.NewAppError("handler", "api.handler.not_registered.app_error", nil, "", http.StatusTeapot)
+	c.Err = model.NewAppError("handler", SOME_ERROR_ID, nil, "", http.StatusBadRequest)
+}
+`
+
+	constructed := findConstructedAppErrors(t, "handler.go", src)
+	require.Len(t, constructed, 2, "only literal ids can be checked")
+	assert.Equal(t, http.StatusBadRequest, constructed[0].Status)
+	assert.Equal(t, 0, constructed[1].Status)
+
+	unregistered := unregisteredAppErrors(constructed)
+	require.Len(t, unregistered, 1)
+	assert.Equal(t, "api.handler.not_registered.app_error", unregistered[0].Id)
+	assert.Equal(t, "handler.go:5:10", unregistered[0].Position)
+}
+
+// writeAppErrorCatalog rewrites the catalog with the AppErrors added to it, keeping the codes of the ones in it already.
+func writeAppErrorCatalog(t *testing.T, added []*constructedAppError) {
+	entries := model.GetAppErrorCatalog()
+
+	codes := map[string]bool{}
+	for _, entry := range entries {
+		codes[entry.Code] = true
+	}
+
+	ids := map[string]bool{}
+	for _, appErr := range added {
+		if ids[appErr.Id] {
+			continue
+		}
+
+		require.NotZero(t, appErr.Status, "%v: %v needs to be constructed with a status from net/http to be added", appErr.Position, appErr.Id)
+
+		entry := &model.AppErrorCatalogEntry{
+			Id:         appErr.Id,
+			Code:       newAppErrorCode(appErr.Id, codes),
+			StatusCode: appErr.Status,
+		}
+		entries = append(entries, entry)
+		codes[entry.Code] = true
+		ids[entry.Id] = true
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Id < entries[j].Id
+	})
+
+	statusNames := map[int]string{}
+	for name, status := range httpStatuses {
+		statusNames[status] = "http." + name
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(appErrorCatalogHeader)
+	for _, entry := range entries {
+		status, ok := statusNames[entry.StatusCode]
+		if !ok {
+			status = strconv.Itoa(entry.StatusCode)
+		}
+		fmt.Fprintf(&buf, "\t{Id: %q, Code: %q, StatusCode: %v},\n", entry.Id, entry.Code, status)
+	}
+	buf.WriteString("}\n")
+
+	src, err := format.Source(buf.Bytes())
+	require.Nil(t, err)
+	require.Nil(t, ioutil.WriteFile(appErrorCatalogFile, src, 0644))
+}
+
+// newAppErrorCode makes a code for an AppError from its id, leaving out the parts that every id has in common unless
+// that would give it the same code as one that's already registered.
+func newAppErrorCode(id string, codes map[string]bool) string {
+	full := strings.TrimSuffix(id, ".app_error")
+
+	code := strings.TrimPrefix(strings.TrimPrefix(full, "api."), "app.")
+	if codes[code] || !strings.Contains(code, ".") {
+		return full
+	}
+
+	return code
+}
@@ -4,15 +4,22 @@
 package api4
 
 import (
+	"fmt"
+	"hash/crc32"
 	"net/http"
+	"strconv"
+	"time"
 
-	"github.com/mattermost/mattermost-server/mlog"
 	"github.com/mattermost/mattermost-server/model"
 )
 
+// CHANNEL_FEED_MAX_AGE is how many seconds feed readers and proxies may cache a channel's feed for.
+const CHANNEL_FEED_MAX_AGE = 300
+
 func (api *API) InitChannel() {
 	api.BaseRoutes.Channels.Handle("", api.ApiSessionRequired(createChannel)).Methods("POST")
 	api.BaseRoutes.Channels.Handle("/direct", api.ApiSessionRequired(createDirectChannel)).Methods("POST")
+	api.BaseRoutes.Channels.Handle("/direct/{user_id:[A-Za-z0-9]+}", api.ApiSessionRequired(getDirectChannelWithUser)).Methods("GET")
 	api.BaseRoutes.Channels.Handle("/group", api.ApiSessionRequired(createGroupChannel)).Methods("POST")
 	api.BaseRoutes.Channels.Handle("/members/{user_id:[A-Za-z0-9]+}/view", api.ApiSessionRequired(viewChannel)).Methods("POST")
 
@@ -22,24 +29,40 @@ func (api *API) InitChannel() {
 	api.BaseRoutes.ChannelsForTeam.Handle("/search", api.ApiSessionRequired(searchChannelsForTeam)).Methods("POST")
 	api.BaseRoutes.ChannelsForTeam.Handle("/autocomplete", api.ApiSessionRequired(autocompleteChannelsForTeam)).Methods("GET")
 	api.BaseRoutes.User.Handle("/teams/{team_id:[A-Za-z0-9]+}/channels", api.ApiSessionRequired(getChannelsForTeamForUser)).Methods("GET")
+	api.BaseRoutes.User.Handle("/teams/{team_id:[A-Za-z0-9]+}/channels/unreads", api.ApiSessionRequired(getChannelUnreadsForTeamForUser)).Methods("GET")
+	api.BaseRoutes.User.Handle("/channels/unreads", api.ApiSessionRequired(getChannelUnreadsForUser)).Methods("GET")
+	api.BaseRoutes.User.Handle("/teams/{team_id:[A-Za-z0-9]+}/channels/read", api.ApiSessionRequired(markChannelsAsReadForTeamForUser)).Methods("POST")
+	api.BaseRoutes.User.Handle("/channels/read", api.ApiSessionRequired(markChannelsAsReadForUser)).Methods("POST")
+	api.BaseRoutes.User.Handle("/channels/notify_props", api.ApiSessionRequired(updateChannelMembersNotifyProps)).Methods("PUT")
 
 	api.BaseRoutes.Channel.Handle("", api.ApiSessionRequired(getChannel)).Methods("GET")
 	api.BaseRoutes.Channel.Handle("", api.ApiSessionRequired(updateChannel)).Methods("PUT")
 	api.BaseRoutes.Channel.Handle("/patch", api.ApiSessionRequired(patchChannel)).Methods("PUT")
-	api.BaseRoutes.Channel.Handle("/convert", api.ApiSessionRequired(convertChannelToPrivate)).Methods("POST")
+	api.BaseRoutes.Channel.Handle("/convert", api.ApiSessionRequired(convertChannel)).Methods("POST")
+	api.BaseRoutes.Channel.Handle("/image", api.ApiSessionRequiredTrustRequester(getChannelIcon)).Methods("GET")
+	api.BaseRoutes.Channel.Handle("/image", api.ApiSessionRequired(setChannelIcon)).Methods("POST")
+	api.BaseRoutes.Channel.Handle("/image", api.ApiSessionRequired(removeChannelIcon)).Methods("DELETE")
 	api.BaseRoutes.Channel.Handle("/restore", api.ApiSessionRequired(restoreChannel)).Methods("POST")
 	api.BaseRoutes.Channel.Handle("", api.ApiSessionRequired(deleteChannel)).Methods("DELETE")
 	api.BaseRoutes.Channel.Handle("/stats", api.ApiSessionRequired(getChannelStats)).Methods("GET")
 	api.BaseRoutes.Channel.Handle("/pinned", api.ApiSessionRequired(getPinnedPosts)).Methods("GET")
+	api.BaseRoutes.Channel.Handle("/feed", api.ApiSessionRequired(getChannelFeed)).Methods("GET")
+	api.BaseRoutes.Channel.Handle("/feed/regenerate_token", api.ApiSessionRequired(regenerateChannelFeedToken)).Methods("POST")
+	api.BaseRoutes.Channel.Handle("/feed.atom", api.ApiHandler(getChannelFeedAtom)).Methods("GET")
 
 	api.BaseRoutes.ChannelForUser.Handle("/unread", api.ApiSessionRequired(getChannelUnread)).Methods("GET")
 
 	api.BaseRoutes.ChannelByName.Handle("", api.ApiSessionRequired(getChannelByName)).Methods("GET")
+	api.BaseRoutes.Team.Handle("/channels/name/@{username:[A-Za-z0-9\\_\\-\\.]+}", api.ApiSessionRequired(getDirectChannelByUsername)).Methods("GET")
 	api.BaseRoutes.ChannelByNameForTeamName.Handle("", api.ApiSessionRequired(getChannelByNameForTeamName)).Methods("GET")
 
 	api.BaseRoutes.ChannelMembers.Handle("", api.ApiSessionRequired(getChannelMembers)).Methods("GET")
+	api.BaseRoutes.ChannelMemberHistory.Handle("", api.ApiSessionRequired(getChannelMemberHistory)).Methods("GET")
 	api.BaseRoutes.ChannelMembers.Handle("/ids", api.ApiSessionRequired(getChannelMembersByIds)).Methods("POST")
 	api.BaseRoutes.ChannelMembers.Handle("", api.ApiSessionRequired(addChannelMember)).Methods("POST")
+	api.BaseRoutes.ChannelMembers.Handle("", api.ApiSessionRequired(addGroupChannelMembers)).Methods("PUT")
+	api.BaseRoutes.ChannelMembers.Handle("", api.ApiSessionRequired(removeGroupChannelMembers)).Methods("DELETE")
+	api.BaseRoutes.ChannelMembers.Handle("/roles", api.ApiSessionRequired(updateChannelMembersRoles)).Methods("PUT")
 	api.BaseRoutes.ChannelMembersForUser.Handle("", api.ApiSessionRequired(getChannelMembersForUser)).Methods("GET")
 	api.BaseRoutes.ChannelMember.Handle("", api.ApiSessionRequired(getChannelMember)).Methods("GET")
 	api.BaseRoutes.ChannelMember.Handle("", api.ApiSessionRequired(removeChannelMember)).Methods("DELETE")
@@ -64,14 +87,23 @@ func createChannel(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if sc, err := c.App.CreateChannelWithUser(channel, c.Session.UserId); err != nil {
+	var sc *model.Channel
+	var err *model.AppError
+	if c.IsLocal {
+		// There's no user to add to the channel over the local mode socket
+		sc, err = c.App.CreateChannel(channel, false)
+	} else {
+		sc, err = c.App.CreateChannelWithUser(channel, c.Session.UserId)
+	}
+
+	if err != nil {
 		c.Err = err
 		return
-	} else {
-		c.LogAudit("name=" + channel.Name)
-		w.WriteHeader(http.StatusCreated)
-		w.Write([]byte(sc.ToJson()))
 	}
+
+	c.LogAudit("name=" + channel.Name)
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(sc.ToJson()))
 }
 
 func updateChannel(c *Context, w http.ResponseWriter, r *http.Request) {
@@ -117,6 +149,12 @@ func updateChannel(c *Context, w http.ResponseWriter, r *http.Request) {
 
 	oldChannel.Header = channel.Header
 	oldChannel.Purpose = channel.Purpose
+	oldChannel.DisableGroupMentions = channel.DisableGroupMentions
+	oldChannel.DisableChannelMentions = channel.DisableChannelMentions
+	oldChannel.JoinLeaveMessages = channel.JoinLeaveMessages
+	oldChannel.DisableCustomCommands = channel.DisableCustomCommands
+	oldChannel.DisabledCommandTriggers = channel.DisabledCommandTriggers
+	oldChannel.DisableReactions = channel.DisableReactions
 
 	oldChannelDisplayName := oldChannel.DisplayName
 
@@ -138,7 +176,7 @@ func updateChannel(c *Context, w http.ResponseWriter, r *http.Request) {
 	} else {
 		if oldChannelDisplayName != channel.DisplayName {
 			if err := c.App.PostUpdateChannelDisplayNameMessage(c.Session.UserId, channel, oldChannelDisplayName, channel.DisplayName); err != nil {
-				mlog.Error(err.Error())
+				c.Log.Error(err.Error())
 			}
 		}
 
@@ -147,25 +185,34 @@ func updateChannel(c *Context, w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func convertChannelToPrivate(c *Context, w http.ResponseWriter, r *http.Request) {
+// convertChannel makes a channel private, or public if requested. It responds with the channel and the integrations
+// that can read from or post to it.
+func convertChannel(c *Context, w http.ResponseWriter, r *http.Request) {
 	c.RequireChannelId()
 	if c.Err != nil {
 		return
 	}
 
-	oldPublicChannel, err := c.App.GetChannel(c.Params.ChannelId)
+	channelType := model.MapFromJson(r.Body)["type"]
+	if channelType == "" {
+		channelType = model.CHANNEL_PRIVATE
+	}
+
+	channel, err := c.App.GetChannel(c.Params.ChannelId)
 	if err != nil {
 		c.Err = err
 		return
-	} else if !c.App.SessionHasPermissionToTeam(c.Session, oldPublicChannel.TeamId, model.PERMISSION_MANAGE_TEAM) {
+	}
+
+	if channelType == model.CHANNEL_OPEN {
+		// Making a private channel public exposes its history to the whole team
+		if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+			c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+			return
+		}
+	} else if !c.App.SessionHasPermissionToTeam(c.Session, channel.TeamId, model.PERMISSION_MANAGE_TEAM) {
 		c.SetPermissionError(model.PERMISSION_MANAGE_TEAM)
 		return
-	} else if oldPublicChannel.Type == model.CHANNEL_PRIVATE {
-		c.Err = model.NewAppError("convertChannelToPrivate", "api.channel.convert_channel_to_private.private_channel_error", nil, "", http.StatusBadRequest)
-		return
-	} else if oldPublicChannel.Name == model.DEFAULT_CHANNEL {
-		c.Err = model.NewAppError("convertChannelToPrivate", "api.channel.convert_channel_to_private.default_channel_error", nil, "", http.StatusBadRequest)
-		return
 	}
 
 	var user *model.User
@@ -174,15 +221,14 @@ func convertChannelToPrivate(c *Context, w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	oldPublicChannel.Type = model.CHANNEL_PRIVATE
-
-	if rchannel, err := c.App.UpdateChannelPrivacy(oldPublicChannel, user); err != nil {
+	conversion, err := c.App.ConvertChannel(channel, channelType, user)
+	if err != nil {
 		c.Err = err
 		return
-	} else {
-		c.LogAudit("name=" + rchannel.Name)
-		w.Write([]byte(rchannel.ToJson()))
 	}
+
+	c.LogAudit("name=" + conversion.Name + ", type=" + conversion.Type)
+	w.Write([]byte(conversion.ToJson()))
 }
 
 func patchChannel(c *Context, w http.ResponseWriter, r *http.Request) {
@@ -216,6 +262,119 @@ func patchChannel(c *Context, w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func getChannelIcon(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireChannelId()
+	if c.Err != nil {
+		return
+	}
+
+	channel, err := c.App.GetChannel(c.Params.ChannelId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	if channel.Type == model.CHANNEL_OPEN {
+		if !c.App.SessionHasPermissionToTeam(c.Session, channel.TeamId, model.PERMISSION_READ_PUBLIC_CHANNEL) {
+			c.SetPermissionError(model.PERMISSION_READ_PUBLIC_CHANNEL)
+			return
+		}
+	} else {
+		if !c.App.SessionHasPermissionToChannel(c.Session, c.Params.ChannelId, model.PERMISSION_READ_CHANNEL) {
+			c.SetPermissionError(model.PERMISSION_READ_CHANNEL)
+			return
+		}
+	}
+
+	etag := strconv.FormatInt(channel.LastIconUpdate, 10)
+
+	if c.HandleEtag(etag, "Get Channel Icon", w, r) {
+		return
+	}
+
+	img, err := c.App.GetChannelIcon(channel)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%v, public", 24*60*60)) // 24 hrs
+	w.Header().Set(model.HEADER_ETAG_SERVER, etag)
+	w.Write(img)
+}
+
+func setChannelIcon(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireChannelId()
+	if c.Err != nil {
+		return
+	}
+
+	channel, err := c.App.GetChannel(c.Params.ChannelId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	if !CanManageChannel(c, channel) {
+		return
+	}
+
+	if r.ContentLength > model.CHANNEL_ICON_MAX_FILE_SIZE {
+		c.Err = model.NewAppError("setChannelIcon", "api.channel.set_channel_icon.too_large.app_error", nil, "", http.StatusBadRequest)
+		return
+	}
+
+	if err := r.ParseMultipartForm(model.CHANNEL_ICON_MAX_FILE_SIZE); err != nil {
+		c.Err = model.NewAppError("setChannelIcon", "api.channel.set_channel_icon.parse.app_error", nil, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	imageArray, ok := r.MultipartForm.File["image"]
+	if !ok || len(imageArray) == 0 {
+		c.Err = model.NewAppError("setChannelIcon", "api.channel.set_channel_icon.no_file.app_error", nil, "", http.StatusBadRequest)
+		return
+	}
+
+	if imageArray[0].Size > model.CHANNEL_ICON_MAX_FILE_SIZE {
+		c.Err = model.NewAppError("setChannelIcon", "api.channel.set_channel_icon.too_large.app_error", nil, "", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.App.SetChannelIcon(c.Params.ChannelId, imageArray[0]); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("")
+	ReturnStatusOK(w)
+}
+
+func removeChannelIcon(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireChannelId()
+	if c.Err != nil {
+		return
+	}
+
+	channel, err := c.App.GetChannel(c.Params.ChannelId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	if !CanManageChannel(c, channel) {
+		return
+	}
+
+	if err := c.App.RemoveChannelIcon(c.Params.ChannelId); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("")
+	ReturnStatusOK(w)
+}
+
 func restoreChannel(c *Context, w http.ResponseWriter, r *http.Request) {
 	c.RequireChannelId()
 	if c.Err != nil {
@@ -235,6 +394,12 @@ func restoreChannel(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Only system admins can mark channels for permanent deletion, so only they can take them back
+	if channel.PermanentDeleteAt != 0 && !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
 	channel, err = c.App.RestoreChannel(channel)
 	if err != nil {
 		c.Err = err
@@ -246,6 +411,95 @@ func restoreChannel(c *Context, w http.ResponseWriter, r *http.Request) {
 
 }
 
+func getChannelFeed(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireChannelId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionToChannel(c.Session, c.Params.ChannelId, model.PERMISSION_MANAGE_PUBLIC_CHANNEL_PROPERTIES) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_PUBLIC_CHANNEL_PROPERTIES)
+		return
+	}
+
+	channel, err := c.App.GetChannel(c.Params.ChannelId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	feed, err := c.App.GetChannelFeed(channel, c.GetSiteURLHeader())
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(feed.ToJson()))
+}
+
+func regenerateChannelFeedToken(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireChannelId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionToChannel(c.Session, c.Params.ChannelId, model.PERMISSION_MANAGE_PUBLIC_CHANNEL_PROPERTIES) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_PUBLIC_CHANNEL_PROPERTIES)
+		return
+	}
+
+	channel, err := c.App.GetChannel(c.Params.ChannelId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	feed, err := c.App.RegenerateChannelFeedToken(channel, c.GetSiteURLHeader())
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("name=" + channel.Name)
+	w.Write([]byte(feed.ToJson()))
+}
+
+// getChannelFeedAtom serves a channel's feed to anyone with its token, since feed readers can't log in.
+func getChannelFeedAtom(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireChannelId()
+	if c.Err != nil {
+		return
+	}
+
+	channel, err := c.App.GetChannel(c.Params.ChannelId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	if err = c.App.CheckChannelFeedToken(channel, r.URL.Query().Get("token")); err != nil {
+		c.Err = err
+		return
+	}
+
+	feed, updatedAt, err := c.App.GetChannelFeedAtom(channel, c.GetSiteURLHeader())
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	etag := model.Etag(channel.Id, updatedAt, crc32.ChecksumIEEE(feed))
+	if c.HandleEtag(etag, "Get Channel Feed", w, r) {
+		return
+	}
+
+	w.Header().Set("Content-Type", model.CHANNEL_FEED_CONTENT_TYPE)
+	w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%v, public", CHANNEL_FEED_MAX_AGE))
+	w.Header().Set("Last-Modified", time.Unix(0, updatedAt*int64(time.Millisecond)).UTC().Format(http.TimeFormat))
+	w.Header().Set(model.HEADER_ETAG_SERVER, etag)
+	w.Write(feed)
+}
+
 func CanManageChannel(c *Context, channel *model.Channel) bool {
 	if channel.Type == model.CHANNEL_OPEN && !c.App.SessionHasPermissionToChannel(c.Session, channel.Id, model.PERMISSION_MANAGE_PUBLIC_CHANNEL_PROPERTIES) {
 		c.SetPermissionError(model.PERMISSION_MANAGE_PUBLIC_CHANNEL_PROPERTIES)
@@ -289,6 +543,16 @@ func createDirectChannel(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	for _, id := range userIds {
+		if canSee, err := c.App.SessionCanSeeUser(c.Session, id); err != nil {
+			c.Err = err
+			return
+		} else if !canSee {
+			c.Err = model.NewAppError("createDirectChannel", "api.user.guest_restricted.app_error", nil, "", http.StatusForbidden)
+			return
+		}
+	}
+
 	if sc, err := c.App.CreateDirectChannel(userIds[0], userIds[1]); err != nil {
 		c.Err = err
 		return
@@ -298,6 +562,35 @@ func createDirectChannel(c *Context, w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func getDirectChannelWithUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId()
+	if c.Err != nil {
+		return
+	}
+
+	create, _ := strconv.ParseBool(r.URL.Query().Get("create"))
+	if create && !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_CREATE_DIRECT_CHANNEL) {
+		c.SetPermissionError(model.PERMISSION_CREATE_DIRECT_CHANNEL)
+		return
+	}
+
+	if canSee, err := c.App.SessionCanSeeUser(c.Session, c.Params.UserId); err != nil {
+		c.Err = err
+		return
+	} else if !canSee {
+		c.Err = model.NewAppError("getDirectChannelWithUser", "api.user.guest_restricted.app_error", nil, "", http.StatusForbidden)
+		return
+	}
+
+	channel, err := c.App.LookupDirectChannel(c.Session.UserId, c.Params.UserId, create)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(channel.ToJson()))
+}
+
 func createGroupChannel(c *Context, w http.ResponseWriter, r *http.Request) {
 	userIds := model.ArrayFromJson(r.Body)
 
@@ -326,6 +619,19 @@ func createGroupChannel(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if c.Session.IsGuest() {
+		if users, err := c.App.GetUsersByIds(userIds, false); err != nil {
+			c.Err = err
+			return
+		} else if visible, err := c.App.RestrictUsersToUserChannels(c.Session.UserId, users); err != nil {
+			c.Err = err
+			return
+		} else if len(visible) != len(users) {
+			c.Err = model.NewAppError("createGroupChannel", "api.user.guest_restricted.app_error", nil, "", http.StatusForbidden)
+			return
+		}
+	}
+
 	if groupChannel, err := c.App.CreateGroupChannel(userIds, c.Session.UserId); err != nil {
 		c.Err = err
 		return
@@ -359,6 +665,8 @@ func getChannel(c *Context, w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	channel = c.App.SetDirectChannelDisplayName(channel, c.Session.UserId)
+
 	w.Write([]byte(channel.ToJson()))
 }
 
@@ -387,6 +695,100 @@ func getChannelUnread(c *Context, w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(channelUnread.ToJson()))
 }
 
+func getChannelUnreadsForTeamForUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId().RequireTeamId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionToUser(c.Session, c.Params.UserId) {
+		c.SetPermissionError(model.PERMISSION_EDIT_OTHER_USERS)
+		return
+	}
+
+	if !c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_VIEW_TEAM) {
+		c.SetPermissionError(model.PERMISSION_VIEW_TEAM)
+		return
+	}
+
+	channelUnreads, err := c.App.GetChannelUnreadsForUser(c.Params.TeamId, c.Params.UserId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(model.ChannelUnreadsToJson(channelUnreads)))
+}
+
+func getChannelUnreadsForUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionToUser(c.Session, c.Params.UserId) {
+		c.SetPermissionError(model.PERMISSION_EDIT_OTHER_USERS)
+		return
+	}
+
+	channelUnreads, err := c.App.GetChannelUnreadsForUser("", c.Params.UserId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(model.ChannelUnreadsToJson(channelUnreads)))
+}
+
+func markChannelsAsReadForTeamForUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId().RequireTeamId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionToUser(c.Session, c.Params.UserId) {
+		c.SetPermissionError(model.PERMISSION_EDIT_OTHER_USERS)
+		return
+	}
+
+	if !c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_VIEW_TEAM) {
+		c.SetPermissionError(model.PERMISSION_VIEW_TEAM)
+		return
+	}
+
+	channelIds, err := c.App.MarkChannelsAsViewed(c.Params.UserId, c.Params.TeamId, !c.Session.IsMobileApp())
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.App.UpdateLastActivityAtIfNeeded(c.Session)
+
+	w.Write([]byte(model.ArrayToJson(channelIds)))
+}
+
+func markChannelsAsReadForUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionToUser(c.Session, c.Params.UserId) {
+		c.SetPermissionError(model.PERMISSION_EDIT_OTHER_USERS)
+		return
+	}
+
+	channelIds, err := c.App.MarkChannelsAsViewed(c.Params.UserId, "", !c.Session.IsMobileApp())
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.App.UpdateLastActivityAtIfNeeded(c.Session)
+
+	w.Write([]byte(model.ArrayToJson(channelIds)))
+}
+
 func getChannelStats(c *Context, w http.ResponseWriter, r *http.Request) {
 	c.RequireChannelId()
 	if c.Err != nil {
@@ -405,7 +807,13 @@ func getChannelStats(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	stats := model.ChannelStats{ChannelId: c.Params.ChannelId, MemberCount: memberCount}
+	guestCount, err := c.App.GetChannelGuestCount(c.Params.ChannelId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	stats := model.ChannelStats{ChannelId: c.Params.ChannelId, MemberCount: memberCount, GuestCount: guestCount}
 	w.Write([]byte(stats.ToJson()))
 }
 
@@ -427,12 +835,12 @@ func getPinnedPosts(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	} else {
 		w.Header().Set(model.HEADER_ETAG_SERVER, posts.Etag())
-		w.Write([]byte(c.App.PostListWithProxyAddedToImageURLs(posts).ToJson()))
+		w.Write([]byte(c.App.PostListWithProxyAddedToImageURLs(postListWithMetadata(c, posts)).ToJson()))
 	}
 }
 
 func getPublicChannelsForTeam(c *Context, w http.ResponseWriter, r *http.Request) {
-	c.RequireTeamId()
+	c.RequireTeamId().RequireAllowedFields(model.CHANNEL_SPARSE_FIELDS)
 	if c.Err != nil {
 		return
 	}
@@ -446,7 +854,7 @@ func getPublicChannelsForTeam(c *Context, w http.ResponseWriter, r *http.Request
 		c.Err = err
 		return
 	} else {
-		w.Write([]byte(channels.ToJson()))
+		w.Write([]byte(channels.ToSparseJson(c.Params.Fields)))
 		return
 	}
 }
@@ -504,7 +912,7 @@ func getPublicChannelsByIdsForTeam(c *Context, w http.ResponseWriter, r *http.Re
 }
 
 func getChannelsForTeamForUser(c *Context, w http.ResponseWriter, r *http.Request) {
-	c.RequireUserId().RequireTeamId()
+	c.RequireUserId().RequireTeamId().RequireAllowedFields(model.CHANNEL_SPARSE_FIELDS)
 	if c.Err != nil {
 		return
 	}
@@ -526,7 +934,7 @@ func getChannelsForTeamForUser(c *Context, w http.ResponseWriter, r *http.Reques
 		return
 	} else {
 		w.Header().Set(model.HEADER_ETAG_SERVER, channels.Etag())
-		w.Write([]byte(channels.ToJson()))
+		w.Write([]byte(channels.ToSparseJson(c.Params.Fields)))
 	}
 }
 
@@ -536,14 +944,22 @@ func autocompleteChannelsForTeam(c *Context, w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if !c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_LIST_TEAM_CHANNELS) {
+	name := r.URL.Query().Get("name")
+
+	var channels *model.ChannelList
+	var err *model.AppError
+
+	// Guests can't list the team's channels, but can still find the channels they belong to
+	if c.Session.IsGuest() && c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_VIEW_TEAM) {
+		channels, err = c.App.SearchChannelsForUser(c.Session.UserId, c.Params.TeamId, name)
+	} else if !c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_LIST_TEAM_CHANNELS) {
 		c.SetPermissionError(model.PERMISSION_LIST_TEAM_CHANNELS)
 		return
+	} else {
+		channels, err = c.App.AutocompleteChannels(c.Params.TeamId, name)
 	}
 
-	name := r.URL.Query().Get("name")
-
-	if channels, err := c.App.AutocompleteChannels(c.Params.TeamId, name); err != nil {
+	if err != nil {
 		c.Err = err
 		return
 	} else {
@@ -563,12 +979,19 @@ func searchChannelsForTeam(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_LIST_TEAM_CHANNELS) {
+	var channels *model.ChannelList
+	var err *model.AppError
+
+	if c.Session.IsGuest() && c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_VIEW_TEAM) {
+		channels, err = c.App.SearchChannelsForUser(c.Session.UserId, c.Params.TeamId, props.Term)
+	} else if !c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_LIST_TEAM_CHANNELS) {
 		c.SetPermissionError(model.PERMISSION_LIST_TEAM_CHANNELS)
 		return
+	} else {
+		channels, err = c.App.SearchChannels(c.Params.TeamId, props.Term)
 	}
 
-	if channels, err := c.App.SearchChannels(c.Params.TeamId, props.Term); err != nil {
+	if err != nil {
 		c.Err = err
 		return
 	} else {
@@ -599,6 +1022,22 @@ func deleteChannel(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if c.Params.Permanent && *c.App.Config().ServiceSettings.EnableAPIChannelDeletion {
+		if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+			c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+			return
+		}
+
+		if _, err = c.App.MarkChannelForPermanentDeletion(channel, c.Session.UserId); err != nil {
+			c.Err = err
+			return
+		}
+
+		c.LogAudit("name=" + channel.Name + " permanent=true")
+		ReturnStatusOK(w)
+		return
+	}
+
 	err = c.App.DeleteChannel(channel, c.Session.UserId)
 	if err != nil {
 		c.Err = err
@@ -636,6 +1075,28 @@ func getChannelByName(c *Context, w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	channel = c.App.SetDirectChannelDisplayName(channel, c.Session.UserId)
+
+	w.Write([]byte(channel.ToJson()))
+}
+
+func getDirectChannelByUsername(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireTeamId().RequireUsername()
+	if c.Err != nil {
+		return
+	}
+
+	channel, err := c.App.GetDirectChannelByUsername(c.Session.UserId, c.Params.Username)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	if !c.App.SessionHasPermissionToChannel(c.Session, channel.Id, model.PERMISSION_READ_CHANNEL) {
+		c.SetPermissionError(model.PERMISSION_READ_CHANNEL)
+		return
+	}
+
 	w.Write([]byte(channel.ToJson()))
 }
 
@@ -658,6 +1119,8 @@ func getChannelByNameForTeamName(c *Context, w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	channel = c.App.SetDirectChannelDisplayName(channel, c.Session.UserId)
+
 	w.Write([]byte(channel.ToJson()))
 }
 
@@ -680,6 +1143,42 @@ func getChannelMembers(c *Context, w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func getChannelMemberHistory(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireChannelId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	var since, until int64
+	query := r.URL.Query()
+	if sinceString := query.Get("since"); sinceString != "" {
+		var err error
+		if since, err = strconv.ParseInt(sinceString, 10, 64); err != nil {
+			c.SetInvalidUrlParam("since")
+			return
+		}
+	}
+	if untilString := query.Get("until"); untilString != "" {
+		var err error
+		if until, err = strconv.ParseInt(untilString, 10, 64); err != nil {
+			c.SetInvalidUrlParam("until")
+			return
+		}
+	}
+
+	if history, err := c.App.GetChannelMemberHistory(c.Params.ChannelId, since, until, c.Params.Page, c.Params.PerPage); err != nil {
+		c.Err = err
+		return
+	} else {
+		w.Write([]byte(model.ChannelMemberHistoryResultListToJson(history)))
+	}
+}
+
 func getChannelMembersByIds(c *Context, w http.ResponseWriter, r *http.Request) {
 	c.RequireChannelId()
 	if c.Err != nil {
@@ -802,7 +1301,44 @@ func updateChannelMemberRoles(c *Context, w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if _, err := c.App.UpdateChannelMemberRoles(c.Params.ChannelId, c.Params.UserId, newRoles); err != nil {
+	if _, err := c.App.UpdateChannelMemberRolesByActor(c.Params.ChannelId, c.Params.UserId, newRoles, c.Session.UserId); err != nil {
+		c.Err = err
+		return
+	}
+
+	ReturnStatusOK(w)
+}
+
+func updateChannelMembersRoles(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireChannelId()
+	if c.Err != nil {
+		return
+	}
+
+	update := model.ChannelMembersRolesFromJson(r.Body)
+	if update == nil || len(update.UserIds) == 0 {
+		c.SetInvalidParam("user_ids")
+		return
+	}
+
+	for _, userId := range update.UserIds {
+		if !model.IsValidId(userId) {
+			c.SetInvalidParam("user_ids")
+			return
+		}
+	}
+
+	if !model.IsValidUserRoles(update.Roles) {
+		c.SetInvalidParam("roles")
+		return
+	}
+
+	if !c.App.SessionHasPermissionToChannel(c.Session, c.Params.ChannelId, model.PERMISSION_MANAGE_CHANNEL_ROLES) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_CHANNEL_ROLES)
+		return
+	}
+
+	if _, err := c.App.UpdateChannelMembersRoles(c.Params.ChannelId, update.UserIds, update.Roles, c.Session.UserId); err != nil {
 		c.Err = err
 		return
 	}
@@ -836,6 +1372,38 @@ func updateChannelMemberNotifyProps(c *Context, w http.ResponseWriter, r *http.R
 	ReturnStatusOK(w)
 }
 
+func updateChannelMembersNotifyProps(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId()
+	if c.Err != nil {
+		return
+	}
+
+	propsByChannel := model.ChannelNotifyPropsByChannelFromJson(r.Body)
+	if propsByChannel == nil {
+		c.SetInvalidParam("notify_props")
+		return
+	}
+
+	for channelId := range propsByChannel {
+		if !model.IsValidId(channelId) {
+			c.SetInvalidParam("channel_id")
+			return
+		}
+	}
+
+	if !c.App.SessionHasPermissionToUser(c.Session, c.Params.UserId) {
+		c.SetPermissionError(model.PERMISSION_EDIT_OTHER_USERS)
+		return
+	}
+
+	if _, err := c.App.UpdateChannelMembersNotifyProps(c.Params.UserId, propsByChannel); err != nil {
+		c.Err = err
+		return
+	}
+
+	ReturnStatusOK(w)
+}
+
 func addChannelMember(c *Context, w http.ResponseWriter, r *http.Request) {
 	c.RequireChannelId()
 	if c.Err != nil {
@@ -886,14 +1454,14 @@ func addChannelMember(c *Context, w http.ResponseWriter, r *http.Request) {
 			}
 		} else {
 			if !c.App.SessionHasPermissionToChannel(c.Session, channel.Id, model.PERMISSION_MANAGE_PUBLIC_CHANNEL_MEMBERS) {
-				c.SetPermissionError(model.PERMISSION_MANAGE_PUBLIC_CHANNEL_MEMBERS)
+				setChannelPermissionError(c, channel.Id, model.PERMISSION_MANAGE_PUBLIC_CHANNEL_MEMBERS)
 				return
 			}
 		}
 	}
 
 	if channel.Type == model.CHANNEL_PRIVATE && !c.App.SessionHasPermissionToChannel(c.Session, channel.Id, model.PERMISSION_MANAGE_PRIVATE_CHANNEL_MEMBERS) {
-		c.SetPermissionError(model.PERMISSION_MANAGE_PRIVATE_CHANNEL_MEMBERS)
+		setChannelPermissionError(c, channel.Id, model.PERMISSION_MANAGE_PRIVATE_CHANNEL_MEMBERS)
 		return
 	}
 
@@ -927,17 +1495,24 @@ func removeChannelMember(c *Context, w http.ResponseWriter, r *http.Request) {
 
 	if c.Params.UserId != c.Session.UserId {
 		if channel.Type == model.CHANNEL_OPEN && !c.App.SessionHasPermissionToChannel(c.Session, channel.Id, model.PERMISSION_MANAGE_PUBLIC_CHANNEL_MEMBERS) {
-			c.SetPermissionError(model.PERMISSION_MANAGE_PUBLIC_CHANNEL_MEMBERS)
+			setChannelPermissionError(c, channel.Id, model.PERMISSION_MANAGE_PUBLIC_CHANNEL_MEMBERS)
 			return
 		}
 
 		if channel.Type == model.CHANNEL_PRIVATE && !c.App.SessionHasPermissionToChannel(c.Session, channel.Id, model.PERMISSION_MANAGE_PRIVATE_CHANNEL_MEMBERS) {
-			c.SetPermissionError(model.PERMISSION_MANAGE_PRIVATE_CHANNEL_MEMBERS)
+			setChannelPermissionError(c, channel.Id, model.PERMISSION_MANAGE_PRIVATE_CHANNEL_MEMBERS)
 			return
 		}
 	}
 
-	if err = c.App.RemoveUserFromChannel(c.Params.UserId, c.Session.UserId, channel); err != nil {
+	if channel.Type == model.CHANNEL_GROUP {
+		// Group channels are renamed after their members, so removing someone goes through the same checks as when
+		// several are removed at once
+		if _, err = c.App.RemoveUsersFromGroupChannel(channel, []string{c.Params.UserId}, c.Session.UserId); err != nil {
+			c.Err = err
+			return
+		}
+	} else if err = c.App.RemoveUserFromChannel(c.Params.UserId, c.Session.UserId, channel); err != nil {
 		c.Err = err
 		return
 	}
@@ -946,3 +1521,59 @@ func removeChannelMember(c *Context, w http.ResponseWriter, r *http.Request) {
 
 	ReturnStatusOK(w)
 }
+
+func addGroupChannelMembers(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireChannelId()
+	if c.Err != nil {
+		return
+	}
+
+	userIds := model.ArrayFromJson(r.Body)
+	if len(userIds) == 0 {
+		c.SetInvalidParam("user_ids")
+		return
+	}
+
+	channel, err := c.App.GetChannel(c.Params.ChannelId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	channel, err = c.App.AddUsersToGroupChannel(channel, userIds, c.Session.UserId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("name=" + channel.Name + " user_ids=" + model.ArrayToJson(userIds))
+	w.Write([]byte(channel.ToJson()))
+}
+
+func removeGroupChannelMembers(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireChannelId()
+	if c.Err != nil {
+		return
+	}
+
+	userIds := model.ArrayFromJson(r.Body)
+	if len(userIds) == 0 {
+		c.SetInvalidParam("user_ids")
+		return
+	}
+
+	channel, err := c.App.GetChannel(c.Params.ChannelId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	channel, err = c.App.RemoveUsersFromGroupChannel(channel, userIds, c.Session.UserId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("name=" + channel.Name + " user_ids=" + model.ArrayToJson(userIds))
+	w.Write([]byte(channel.ToJson()))
+}
@@ -0,0 +1,212 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func (api *API) InitChannelCategory() {
+	api.BaseRoutes.ChannelCategories.Handle("", api.ApiSessionRequired(getCategoriesForTeamForUser)).Methods("GET")
+	api.BaseRoutes.ChannelCategories.Handle("", api.ApiSessionRequired(createCategoryForTeamForUser)).Methods("POST")
+	api.BaseRoutes.ChannelCategories.Handle("", api.ApiSessionRequired(updateCategoriesForTeamForUser)).Methods("PUT")
+	api.BaseRoutes.ChannelCategories.Handle("/order", api.ApiSessionRequired(getCategoryOrderForTeamForUser)).Methods("GET")
+	api.BaseRoutes.ChannelCategories.Handle("/order", api.ApiSessionRequired(updateCategoryOrderForTeamForUser)).Methods("PUT")
+	api.BaseRoutes.ChannelCategories.Handle("/{category_id:[A-Za-z0-9]+}", api.ApiSessionRequired(getCategoryForTeamForUser)).Methods("GET")
+	api.BaseRoutes.ChannelCategories.Handle("/{category_id:[A-Za-z0-9]+}", api.ApiSessionRequired(updateCategoryForTeamForUser)).Methods("PUT")
+	api.BaseRoutes.ChannelCategories.Handle("/{category_id:[A-Za-z0-9]+}", api.ApiSessionRequired(deleteCategoryForTeamForUser)).Methods("DELETE")
+}
+
+// checkCategoryPermissions makes sure that the session belongs to the user whose categories are being
+// accessed and that the user is still on the team.
+func checkCategoryPermissions(c *Context) bool {
+	if !c.App.SessionHasPermissionToUser(c.Session, c.Params.UserId) {
+		c.SetPermissionError(model.PERMISSION_EDIT_OTHER_USERS)
+		return false
+	}
+
+	if !c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_VIEW_TEAM) {
+		c.SetPermissionError(model.PERMISSION_VIEW_TEAM)
+		return false
+	}
+
+	return true
+}
+
+func getCategoriesForTeamForUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId().RequireTeamId()
+	if c.Err != nil {
+		return
+	}
+
+	if !checkCategoryPermissions(c) {
+		return
+	}
+
+	categories, err := c.App.GetSidebarCategories(c.Params.UserId, c.Params.TeamId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(categories.ToJson()))
+}
+
+func createCategoryForTeamForUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId().RequireTeamId()
+	if c.Err != nil {
+		return
+	}
+
+	if !checkCategoryPermissions(c) {
+		return
+	}
+
+	category := model.SidebarCategoryWithChannelsFromJson(r.Body)
+	if category == nil {
+		c.SetInvalidParam("category")
+		return
+	}
+
+	created, err := c.App.CreateSidebarCategory(c.Params.UserId, c.Params.TeamId, category)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(created.ToJson()))
+}
+
+func updateCategoriesForTeamForUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId().RequireTeamId()
+	if c.Err != nil {
+		return
+	}
+
+	if !checkCategoryPermissions(c) {
+		return
+	}
+
+	categories := model.SidebarCategoriesWithChannelsFromJson(r.Body)
+	if len(categories) == 0 {
+		c.SetInvalidParam("categories")
+		return
+	}
+
+	updated, err := c.App.UpdateSidebarCategories(c.Params.UserId, c.Params.TeamId, categories)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(model.SidebarCategoriesWithChannelsToJson(updated)))
+}
+
+func getCategoryOrderForTeamForUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId().RequireTeamId()
+	if c.Err != nil {
+		return
+	}
+
+	if !checkCategoryPermissions(c) {
+		return
+	}
+
+	order, err := c.App.GetSidebarCategoryOrder(c.Params.UserId, c.Params.TeamId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(model.ArrayToJson(order)))
+}
+
+func updateCategoryOrderForTeamForUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId().RequireTeamId()
+	if c.Err != nil {
+		return
+	}
+
+	if !checkCategoryPermissions(c) {
+		return
+	}
+
+	order := model.ArrayFromJson(r.Body)
+	if len(order) == 0 {
+		c.SetInvalidParam("order")
+		return
+	}
+
+	if err := c.App.UpdateSidebarCategoryOrder(c.Params.UserId, c.Params.TeamId, order); err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(model.ArrayToJson(order)))
+}
+
+func getCategoryForTeamForUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId().RequireTeamId().RequireCategoryId()
+	if c.Err != nil {
+		return
+	}
+
+	if !checkCategoryPermissions(c) {
+		return
+	}
+
+	category, err := c.App.GetSidebarCategory(c.Params.UserId, c.Params.TeamId, c.Params.CategoryId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(category.ToJson()))
+}
+
+func updateCategoryForTeamForUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId().RequireTeamId().RequireCategoryId()
+	if c.Err != nil {
+		return
+	}
+
+	if !checkCategoryPermissions(c) {
+		return
+	}
+
+	category := model.SidebarCategoryWithChannelsFromJson(r.Body)
+	if category == nil || category.Id != c.Params.CategoryId {
+		c.SetInvalidParam("category")
+		return
+	}
+
+	updated, err := c.App.UpdateSidebarCategories(c.Params.UserId, c.Params.TeamId, []*model.SidebarCategoryWithChannels{category})
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(updated[0].ToJson()))
+}
+
+func deleteCategoryForTeamForUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId().RequireTeamId().RequireCategoryId()
+	if c.Err != nil {
+		return
+	}
+
+	if !checkCategoryPermissions(c) {
+		return
+	}
+
+	if err := c.App.DeleteSidebarCategory(c.Params.UserId, c.Params.TeamId, c.Params.CategoryId); err != nil {
+		c.Err = err
+		return
+	}
+
+	ReturnStatusOK(w)
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestGetSidebarCategoriesForTeamForUser(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	categories, resp := Client.GetSidebarCategoriesForTeamForUser(th.BasicUser.Id, th.BasicTeam.Id, "")
+	CheckNoError(t, resp)
+	require.Len(t, categories.Categories, 3)
+	assert.Len(t, categories.Order, 3)
+	assert.Contains(t, categories.Categories[1].Channels, th.BasicChannel.Id)
+
+	_, resp = Client.GetSidebarCategoriesForTeamForUser("junk", th.BasicTeam.Id, "")
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = Client.GetSidebarCategoriesForTeamForUser(th.BasicUser2.Id, th.BasicTeam.Id, "")
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = Client.GetSidebarCategoriesForTeamForUser(th.BasicUser.Id, model.NewId(), "")
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = th.SystemAdminClient.GetSidebarCategoriesForTeamForUser(th.BasicUser.Id, th.BasicTeam.Id, "")
+	CheckNoError(t, resp)
+
+	Client.Logout()
+	_, resp = Client.GetSidebarCategoriesForTeamForUser(th.BasicUser.Id, th.BasicTeam.Id, "")
+	CheckUnauthorizedStatus(t, resp)
+}
+
+func TestCreateAndUpdateSidebarCategoryForTeamForUser(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+	Client := th.Client
+
+	category, resp := Client.CreateSidebarCategoryForTeamForUser(th.BasicUser.Id, th.BasicTeam.Id, &model.SidebarCategoryWithChannels{
+		SidebarCategory: model.SidebarCategory{DisplayName: "Custom", Type: model.SIDEBAR_CATEGORY_FAVORITES},
+		Channels:        []string{th.BasicChannel.Id},
+	})
+	CheckNoError(t, resp)
+	CheckCreatedStatus(t, resp)
+	assert.Equal(t, model.SIDEBAR_CATEGORY_CUSTOM, category.Type)
+	assert.Equal(t, th.BasicUser.Id, category.UserId)
+
+	_, resp = Client.CreateSidebarCategoryForTeamForUser(th.BasicUser.Id, th.BasicTeam.Id, &model.SidebarCategoryWithChannels{
+		SidebarCategory: model.SidebarCategory{DisplayName: "Custom"},
+		Channels:        []string{model.NewId()},
+	})
+	CheckBadRequestStatus(t, resp)
+
+	category.DisplayName = "Renamed"
+	category.Channels = []string{}
+	updated, resp := Client.UpdateSidebarCategoryForTeamForUser(th.BasicUser.Id, th.BasicTeam.Id, category.Id, category)
+	CheckNoError(t, resp)
+	assert.Equal(t, "Renamed", updated.DisplayName)
+
+	_, resp = Client.UpdateSidebarCategoryForTeamForUser(th.BasicUser.Id, th.BasicTeam.Id, model.NewId(), category)
+	CheckBadRequestStatus(t, resp)
+
+	fetched, resp := Client.GetSidebarCategoryForTeamForUser(th.BasicUser.Id, th.BasicTeam.Id, category.Id, "")
+	CheckNoError(t, resp)
+	assert.Equal(t, "Renamed", fetched.DisplayName)
+
+	_, resp = Client.GetSidebarCategoryForTeamForUser(th.BasicUser.Id, th.BasicTeam.Id, model.NewId(), "")
+	CheckNotFoundStatus(t, resp)
+
+	categories, resp := Client.GetSidebarCategoriesForTeamForUser(th.BasicUser.Id, th.BasicTeam.Id, "")
+	CheckNoError(t, resp)
+	assert.Contains(t, categories.Categories[1].Channels, th.BasicChannel.Id)
+
+	favorites := categories.Categories[0]
+	favorites.Channels = []string{th.BasicChannel.Id}
+	_, resp = Client.UpdateSidebarCategoriesForTeamForUser(th.BasicUser.Id, th.BasicTeam.Id, []*model.SidebarCategoryWithChannels{favorites})
+	CheckNoError(t, resp)
+
+	favorites.DisplayName = "Renamed"
+	_, resp = Client.UpdateSidebarCategoriesForTeamForUser(th.BasicUser.Id, th.BasicTeam.Id, []*model.SidebarCategoryWithChannels{favorites})
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = Client.DeleteSidebarCategoryForTeamForUser(th.BasicUser.Id, th.BasicTeam.Id, favorites.Id)
+	CheckBadRequestStatus(t, resp)
+
+	ok, resp := Client.DeleteSidebarCategoryForTeamForUser(th.BasicUser.Id, th.BasicTeam.Id, category.Id)
+	CheckNoError(t, resp)
+	assert.True(t, ok)
+
+	_, resp = th.Client.DeleteSidebarCategoryForTeamForUser(th.BasicUser2.Id, th.BasicTeam.Id, category.Id)
+	CheckForbiddenStatus(t, resp)
+}
+
+func TestUpdateSidebarCategoryOrderForTeamForUser(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+	Client := th.Client
+
+	order, resp := Client.GetSidebarCategoryOrderForTeamForUser(th.BasicUser.Id, th.BasicTeam.Id, "")
+	CheckNoError(t, resp)
+	require.Len(t, order, 3)
+
+	newOrder := []string{order[2], order[0], order[1]}
+	_, resp = Client.UpdateSidebarCategoryOrderForTeamForUser(th.BasicUser.Id, th.BasicTeam.Id, newOrder)
+	CheckNoError(t, resp)
+
+	order, resp = Client.GetSidebarCategoryOrderForTeamForUser(th.BasicUser.Id, th.BasicTeam.Id, "")
+	CheckNoError(t, resp)
+	assert.Equal(t, newOrder, order)
+
+	_, resp = Client.UpdateSidebarCategoryOrderForTeamForUser(th.BasicUser.Id, th.BasicTeam.Id, newOrder[:2])
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = Client.UpdateSidebarCategoryOrderForTeamForUser(th.BasicUser.Id, th.BasicTeam.Id, []string{})
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = Client.UpdateSidebarCategoryOrderForTeamForUser(th.BasicUser2.Id, th.BasicTeam.Id, newOrder)
+	CheckForbiddenStatus(t, resp)
+}
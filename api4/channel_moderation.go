@@ -0,0 +1,106 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// channelModerationErrorIds are the error ids returned when a channel's moderation settings, rather
+// than the user's roles, are why a permission was withheld. Clients use them to disable the relevant
+// parts of the UI.
+var channelModerationErrorIds = map[string]string{
+	model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS:     "api.channel.moderated.create_posts.app_error",
+	model.CHANNEL_MODERATED_PERMISSION_CREATE_REACTIONS: "api.channel.moderated.create_reactions.app_error",
+	model.CHANNEL_MODERATED_PERMISSION_MANAGE_MEMBERS:   "api.channel.moderated.manage_members.app_error",
+}
+
+func (api *API) InitChannelModeration() {
+	api.BaseRoutes.Channel.Handle("/moderations", api.ApiSessionRequired(getChannelModerations)).Methods("GET")
+	api.BaseRoutes.Channel.Handle("/moderations", api.ApiSessionRequired(patchChannelModerations)).Methods("PUT")
+}
+
+func getChannelModerations(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireChannelId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionToChannel(c.Session, c.Params.ChannelId, model.PERMISSION_READ_CHANNEL) {
+		c.SetPermissionError(model.PERMISSION_READ_CHANNEL)
+		return
+	}
+
+	channel, err := c.App.GetChannel(c.Params.ChannelId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	moderations, err := c.App.GetChannelModerationsForChannel(channel)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(model.ChannelModerationsToJson(moderations)))
+}
+
+func patchChannelModerations(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireChannelId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	patches := model.ChannelModerationsPatchFromJson(r.Body)
+	if patches == nil {
+		c.SetInvalidParam("moderations")
+		return
+	}
+
+	channel, err := c.App.GetChannel(c.Params.ChannelId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	moderations, err := c.App.PatchChannelModerationsForChannel(channel, patches)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("name=" + channel.Name)
+	w.Write([]byte(model.ChannelModerationsToJson(moderations)))
+}
+
+// setChannelPermissionError reports that the session is missing a permission in a channel, using
+// the moderation specific error id when the channel's moderation settings are the reason.
+func setChannelPermissionError(c *Context, channelId string, permission *model.Permission) {
+	name := model.ChannelModeratedPermissionFor(permission.Id)
+	if errorId, ok := channelModerationErrorIds[name]; ok && c.App.IsChannelModerated(channelId, name, c.Session.IsGuest()) {
+		c.Err = model.NewAppError("Permissions", errorId, nil, "userId="+c.Session.UserId+", "+"permission="+permission.Id+", channelId="+channelId, http.StatusForbidden)
+		return
+	}
+
+	c.SetPermissionError(permission)
+}
+
+// setChannelPermissionErrorByPost is setChannelPermissionError for the channel containing a post.
+func setChannelPermissionErrorByPost(c *Context, postId string, permission *model.Permission) {
+	post, err := c.App.GetSinglePost(postId)
+	if err != nil {
+		c.SetPermissionError(permission)
+		return
+	}
+
+	setChannelPermissionError(c, post.ChannelId, permission)
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func channelModerationPatchForTest(name string, members bool) []*model.ChannelModerationPatch {
+	return []*model.ChannelModerationPatch{
+		{Name: model.NewString(name), Roles: &model.ChannelModeratedRolesPatch{Members: model.NewBool(members)}},
+	}
+}
+
+func TestGetAndPatchChannelModerations(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	moderations, resp := Client.GetChannelModerations(th.BasicChannel.Id, "")
+	CheckNoError(t, resp)
+	require.Len(t, moderations, len(model.CHANNEL_MODERATED_PERMISSIONS))
+	assert.True(t, moderations[0].Roles.Members.Value)
+
+	_, resp = Client.PatchChannelModerations(th.BasicChannel.Id, channelModerationPatchForTest(model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS, false))
+	CheckForbiddenStatus(t, resp)
+
+	moderations, resp = th.SystemAdminClient.PatchChannelModerations(th.BasicChannel.Id, channelModerationPatchForTest(model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS, false))
+	CheckNoError(t, resp)
+	for _, moderation := range moderations {
+		assert.Equal(t, moderation.Name != model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS, moderation.Roles.Members.Value, moderation.Name)
+	}
+
+	moderations, resp = Client.GetChannelModerations(th.BasicChannel.Id, "")
+	CheckNoError(t, resp)
+	assert.False(t, moderations[0].Roles.Members.Value)
+
+	_, resp = th.SystemAdminClient.PatchChannelModerations(th.BasicChannel.Id, channelModerationPatchForTest("junk", false))
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = th.SystemAdminClient.PatchChannelModerations(model.NewId(), channelModerationPatchForTest(model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS, false))
+	CheckNotFoundStatus(t, resp)
+
+	_, resp = Client.GetChannelModerations(th.CreatePrivateChannel().Id, "")
+	CheckForbiddenStatus(t, resp)
+
+	townSquare, resp := th.SystemAdminClient.GetChannelByName(model.DEFAULT_CHANNEL, th.BasicTeam.Id, "")
+	CheckNoError(t, resp)
+	_, resp = th.SystemAdminClient.PatchChannelModerations(townSquare.Id, channelModerationPatchForTest(model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS, false))
+	CheckNoError(t, resp)
+
+	Client.Logout()
+	_, resp = Client.GetChannelModerations(th.BasicChannel.Id, "")
+	CheckUnauthorizedStatus(t, resp)
+}
+
+func TestChannelModerationEnforcement(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	t.Run("create_posts", func(t *testing.T) {
+		_, resp := th.SystemAdminClient.PatchChannelModerations(th.BasicChannel.Id, channelModerationPatchForTest(model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS, false))
+		CheckNoError(t, resp)
+		defer th.SystemAdminClient.PatchChannelModerations(th.BasicChannel.Id, channelModerationPatchForTest(model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS, true))
+
+		_, resp = Client.CreatePost(&model.Post{ChannelId: th.BasicChannel.Id, Message: "hello"})
+		CheckForbiddenStatus(t, resp)
+		CheckErrorMessage(t, resp, "api.channel.moderated.create_posts.app_error")
+
+		_, resp = Client.CreatePost(&model.Post{ChannelId: th.BasicChannel2.Id, Message: "hello"})
+		CheckNoError(t, resp)
+
+		_, resp = th.SystemAdminClient.CreatePost(&model.Post{ChannelId: th.BasicChannel.Id, Message: "hello"})
+		CheckNoError(t, resp)
+
+		// Channel admins keep the permission
+		th.App.UpdateChannelMemberRoles(th.BasicChannel.Id, th.BasicUser.Id, model.CHANNEL_USER_ROLE_ID+" "+model.CHANNEL_ADMIN_ROLE_ID)
+		defer th.App.UpdateChannelMemberRoles(th.BasicChannel.Id, th.BasicUser.Id, model.CHANNEL_USER_ROLE_ID)
+
+		_, resp = Client.CreatePost(&model.Post{ChannelId: th.BasicChannel.Id, Message: "hello"})
+		CheckNoError(t, resp)
+	})
+
+	t.Run("create_posts for guests", func(t *testing.T) {
+		_, guestClient := loginGuestForTest(t, th)
+		guestsPatch := func(guests bool) []*model.ChannelModerationPatch {
+			return []*model.ChannelModerationPatch{
+				{Name: model.NewString(model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS), Roles: &model.ChannelModeratedRolesPatch{Guests: model.NewBool(guests)}},
+			}
+		}
+
+		moderations, resp := th.SystemAdminClient.PatchChannelModerations(th.BasicChannel.Id, guestsPatch(false))
+		CheckNoError(t, resp)
+		defer th.SystemAdminClient.PatchChannelModerations(th.BasicChannel.Id, guestsPatch(true))
+		for _, moderation := range moderations {
+			if moderation.Name == model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS {
+				assert.False(t, moderation.Roles.Guests.Value)
+				assert.True(t, moderation.Roles.Members.Value)
+			}
+		}
+
+		_, resp = guestClient.CreatePost(&model.Post{ChannelId: th.BasicChannel.Id, Message: "hello"})
+		CheckForbiddenStatus(t, resp)
+		CheckErrorMessage(t, resp, "api.channel.moderated.create_posts.app_error")
+
+		_, resp = Client.CreatePost(&model.Post{ChannelId: th.BasicChannel.Id, Message: "hello"})
+		CheckNoError(t, resp)
+
+		// Moderating members instead lets guests post again
+		_, resp = th.SystemAdminClient.PatchChannelModerations(th.BasicChannel.Id, guestsPatch(true))
+		CheckNoError(t, resp)
+		_, resp = th.SystemAdminClient.PatchChannelModerations(th.BasicChannel.Id, channelModerationPatchForTest(model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS, false))
+		CheckNoError(t, resp)
+		defer th.SystemAdminClient.PatchChannelModerations(th.BasicChannel.Id, channelModerationPatchForTest(model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS, true))
+
+		_, resp = guestClient.CreatePost(&model.Post{ChannelId: th.BasicChannel.Id, Message: "hello"})
+		CheckNoError(t, resp)
+	})
+
+	t.Run("create_reactions", func(t *testing.T) {
+		reaction := &model.Reaction{UserId: th.BasicUser.Id, PostId: th.BasicPost.Id, EmojiName: "smile"}
+
+		_, resp := Client.SaveReaction(reaction)
+		CheckNoError(t, resp)
+
+		_, resp = th.SystemAdminClient.PatchChannelModerations(th.BasicChannel.Id, channelModerationPatchForTest(model.CHANNEL_MODERATED_PERMISSION_CREATE_REACTIONS, false))
+		CheckNoError(t, resp)
+		defer th.SystemAdminClient.PatchChannelModerations(th.BasicChannel.Id, channelModerationPatchForTest(model.CHANNEL_MODERATED_PERMISSION_CREATE_REACTIONS, true))
+
+		_, resp = Client.DeleteReaction(reaction)
+		CheckForbiddenStatus(t, resp)
+		CheckErrorMessage(t, resp, "api.channel.moderated.create_reactions.app_error")
+
+		reaction.EmojiName = "sad"
+		_, resp = Client.SaveReaction(reaction)
+		CheckForbiddenStatus(t, resp)
+		CheckErrorMessage(t, resp, "api.channel.moderated.create_reactions.app_error")
+	})
+
+	t.Run("manage_members", func(t *testing.T) {
+		user := th.CreateUser()
+		th.LinkUserToTeam(user, th.BasicTeam)
+
+		_, resp := th.SystemAdminClient.PatchChannelModerations(th.BasicChannel.Id, channelModerationPatchForTest(model.CHANNEL_MODERATED_PERMISSION_MANAGE_MEMBERS, false))
+		CheckNoError(t, resp)
+		defer th.SystemAdminClient.PatchChannelModerations(th.BasicChannel.Id, channelModerationPatchForTest(model.CHANNEL_MODERATED_PERMISSION_MANAGE_MEMBERS, true))
+
+		_, resp = Client.AddChannelMember(th.BasicChannel.Id, user.Id)
+		CheckForbiddenStatus(t, resp)
+		CheckErrorMessage(t, resp, "api.channel.moderated.manage_members.app_error")
+
+		_, resp = Client.RemoveUserFromChannel(th.BasicChannel.Id, th.BasicUser2.Id)
+		CheckForbiddenStatus(t, resp)
+		CheckErrorMessage(t, resp, "api.channel.moderated.manage_members.app_error")
+
+		// Members can still join and leave on their own
+		_, resp = Client.RemoveUserFromChannel(th.BasicChannel.Id, th.BasicUser.Id)
+		CheckNoError(t, resp)
+		_, resp = Client.AddChannelMember(th.BasicChannel.Id, th.BasicUser.Id)
+		CheckNoError(t, resp)
+
+		_, resp = th.SystemAdminClient.AddChannelMember(th.BasicChannel.Id, user.Id)
+		CheckNoError(t, resp)
+	})
+}
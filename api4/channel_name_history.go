@@ -0,0 +1,61 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func (api *API) InitChannelNameHistory() {
+	api.BaseRoutes.Channel.Handle("/aliases", api.ApiSessionRequired(getChannelAliases)).Methods("GET")
+	api.BaseRoutes.Channel.Handle("/aliases/{channel_name:[A-Za-z0-9_-]+}", api.ApiSessionRequired(deleteChannelAlias)).Methods("DELETE")
+}
+
+func getChannelAliases(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireChannelId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionToChannel(c.Session, c.Params.ChannelId, model.PERMISSION_READ_CHANNEL) {
+		c.SetPermissionError(model.PERMISSION_READ_CHANNEL)
+		return
+	}
+
+	history, err := c.App.GetChannelNameHistory(c.Params.ChannelId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(model.ChannelNameHistoryListToJson(history)))
+}
+
+func deleteChannelAlias(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireChannelId().RequireChannelName()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	channel, err := c.App.GetChannel(c.Params.ChannelId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	if err := c.App.DeleteChannelNameHistory(channel, c.Params.ChannelName); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("name=" + channel.Name + ", alias=" + c.Params.ChannelName)
+	ReturnStatusOK(w)
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestChannelAliases(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	channel := th.CreatePublicChannel()
+	firstName := channel.Name
+	secondName := "second-" + model.NewId()
+	thirdName := "third-" + model.NewId()
+
+	for _, name := range []string{secondName, thirdName} {
+		var resp *model.Response
+		channel, resp = Client.PatchChannel(channel.Id, &model.ChannelPatch{Name: model.NewString(name)})
+		CheckNoError(t, resp)
+	}
+
+	for _, name := range []string{firstName, secondName} {
+		found, resp := Client.GetChannelByName(name, th.BasicTeam.Id, "")
+		CheckNoError(t, resp)
+		assert.Equal(t, channel.Id, found.Id)
+		assert.Equal(t, thirdName, found.Name)
+		assert.Equal(t, name, found.RedirectedFrom)
+
+		found, resp = Client.GetChannelByNameForTeamName(name, th.BasicTeam.Name, "")
+		CheckNoError(t, resp)
+		assert.Equal(t, channel.Id, found.Id)
+		assert.Equal(t, name, found.RedirectedFrom)
+	}
+
+	found, resp := Client.GetChannelByName(thirdName, th.BasicTeam.Id, "")
+	CheckNoError(t, resp)
+	assert.Equal(t, "", found.RedirectedFrom)
+
+	aliases, resp := Client.GetChannelAliases(channel.Id, "")
+	CheckNoError(t, resp)
+	require.Len(t, aliases, 2)
+	assert.Equal(t, secondName, aliases[0].Name)
+	assert.Equal(t, firstName, aliases[1].Name)
+
+	_, resp = Client.DeleteChannelAlias(channel.Id, secondName)
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = th.SystemAdminClient.DeleteChannelAlias(th.BasicChannel.Id, secondName)
+	CheckNotFoundStatus(t, resp)
+
+	ok, resp := th.SystemAdminClient.DeleteChannelAlias(channel.Id, secondName)
+	CheckNoError(t, resp)
+	assert.True(t, ok)
+
+	_, resp = Client.GetChannelByName(secondName, th.BasicTeam.Id, "")
+	CheckNotFoundStatus(t, resp)
+
+	aliases, resp = Client.GetChannelAliases(channel.Id, "")
+	CheckNoError(t, resp)
+	require.Len(t, aliases, 1)
+	assert.Equal(t, firstName, aliases[0].Name)
+
+	_, resp = Client.GetChannelAliases(th.CreateChannelWithClient(th.SystemAdminClient, model.CHANNEL_PRIVATE).Id, "")
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = th.SystemAdminClient.DeleteChannelAlias(channel.Id, "not-an-alias")
+	CheckNotFoundStatus(t, resp)
+
+	Client.Logout()
+	_, resp = Client.GetChannelAliases(channel.Id, "")
+	CheckUnauthorizedStatus(t, resp)
+}
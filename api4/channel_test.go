@@ -4,15 +4,23 @@
 package api4
 
 import (
+	"bytes"
 	"fmt"
+	"image"
+	_ "image/png"
 	"net/http"
 	"reflect"
 	"sort"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
 	"github.com/mattermost/mattermost-server/utils"
 )
 
@@ -248,6 +256,18 @@ func TestPatchChannel(t *testing.T) {
 		t.Fatal("should not have updated")
 	}
 
+	joinLeaveMessages := model.CHANNEL_JOIN_LEAVE_MESSAGES_OFF
+	channel, resp = Client.PatchChannel(th.BasicChannel.Id, &model.ChannelPatch{JoinLeaveMessages: &joinLeaveMessages})
+	CheckNoError(t, resp)
+
+	if channel.JoinLeaveMessages != model.CHANNEL_JOIN_LEAVE_MESSAGES_OFF {
+		t.Fatal("should have updated join/leave messages")
+	}
+
+	joinLeaveMessages = "junk"
+	_, resp = Client.PatchChannel(th.BasicChannel.Id, &model.ChannelPatch{JoinLeaveMessages: &joinLeaveMessages})
+	CheckBadRequestStatus(t, resp)
+
 	_, resp = Client.PatchChannel("junk", patch)
 	CheckBadRequestStatus(t, resp)
 
@@ -266,6 +286,180 @@ func TestPatchChannel(t *testing.T) {
 	CheckNoError(t, resp)
 }
 
+func TestChannelIcon(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+	channel := th.BasicChannel
+
+	data, err := readTestFile("test.png")
+	require.Nil(t, err)
+
+	_, resp := Client.GetChannelIcon(channel.Id, "")
+	CheckNotFoundStatus(t, resp)
+
+	ok, resp := Client.SetChannelIcon(channel.Id, data)
+	CheckNoError(t, resp)
+	require.True(t, ok)
+
+	rchannel, resp := Client.GetChannel(channel.Id, "")
+	CheckNoError(t, resp)
+	require.NotZero(t, rchannel.LastIconUpdate)
+
+	icon, resp := Client.GetChannelIcon(channel.Id, "")
+	CheckNoError(t, resp)
+	config, _, err := image.DecodeConfig(bytes.NewReader(icon))
+	require.Nil(t, err)
+	assert.Equal(t, model.CHANNEL_ICON_SIZE, config.Width)
+
+	_, resp = Client.GetChannelIcon(channel.Id, resp.Etag)
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	_, resp = Client.SetChannelIcon(channel.Id, []byte("not an image"))
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = Client.SetChannelIcon(channel.Id, make([]byte, model.CHANNEL_ICON_MAX_FILE_SIZE+1))
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = Client.SetChannelIcon(model.NewId(), data)
+	CheckNotFoundStatus(t, resp)
+
+	rchannel, resp = Client.PatchChannel(channel.Id, &model.ChannelPatch{IconEmojiName: model.NewString("smile")})
+	CheckNoError(t, resp)
+	assert.Equal(t, "smile", rchannel.IconEmojiName)
+	assert.Zero(t, rchannel.LastIconUpdate)
+
+	_, resp = Client.PatchChannel(channel.Id, &model.ChannelPatch{IconEmojiName: model.NewString("missing" + model.NewId())})
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = Client.PatchChannel(channel.Id, &model.ChannelPatch{IconEmojiName: model.NewString("not an emoji")})
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = Client.SetChannelIcon(th.BasicPrivateChannel.Id, data)
+	CheckNoError(t, resp)
+
+	ok, resp = Client.RemoveChannelIcon(th.BasicPrivateChannel.Id)
+	CheckNoError(t, resp)
+	require.True(t, ok)
+
+	_, resp = Client.GetChannelIcon(th.BasicPrivateChannel.Id, "")
+	CheckNotFoundStatus(t, resp)
+
+	// Only users who can manage the channel can change its icon, and only members can see a private channel's
+	_, resp = th.SystemAdminClient.SetChannelIcon(th.BasicPrivateChannel.Id, data)
+	CheckNoError(t, resp)
+
+	user := th.CreateUser()
+	th.LinkUserToTeam(user, th.BasicTeam)
+	Client.Login(user.Email, user.Password)
+
+	_, resp = Client.GetChannelIcon(th.BasicPrivateChannel.Id, "")
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = Client.SetChannelIcon(th.BasicPrivateChannel.Id, data)
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = Client.RemoveChannelIcon(th.BasicPrivateChannel.Id)
+	CheckForbiddenStatus(t, resp)
+
+	Client.Logout()
+	_, resp = Client.GetChannelIcon(channel.Id, "")
+	CheckUnauthorizedStatus(t, resp)
+}
+
+func TestPatchChannelHeaderAndPurpose(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+	Client := th.Client
+
+	WebSocketClient, err := th.CreateWebSocketClient()
+	require.Nil(t, err)
+	WebSocketClient.Listen()
+	defer WebSocketClient.Close()
+
+	patchHeader := func(header string) (*model.Channel, *model.Response) {
+		return Client.PatchChannel(th.BasicChannel.Id, &model.ChannelPatch{Header: &header})
+	}
+
+	t.Run("longer than configured", func(t *testing.T) {
+		_, resp := patchHeader(strings.Repeat("h", model.TEAM_SETTINGS_DEFAULT_MAX_CHANNEL_HEADER+1))
+		CheckBadRequestStatus(t, resp)
+		CheckErrorMessage(t, resp, "app.channel.header_too_long.app_error")
+
+		purpose := strings.Repeat("p", model.TEAM_SETTINGS_DEFAULT_MAX_CHANNEL_PURPOSE+1)
+		_, resp = Client.PatchChannel(th.BasicChannel.Id, &model.ChannelPatch{Purpose: &purpose})
+		CheckBadRequestStatus(t, resp)
+		CheckErrorMessage(t, resp, "app.channel.purpose_too_long.app_error")
+	})
+
+	t.Run("raised maximum", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) {
+			*cfg.TeamSettings.MaxChannelHeaderLength = model.CHANNEL_HEADER_MAX_RUNES
+			*cfg.TeamSettings.MaxChannelPurposeLength = model.CHANNEL_PURPOSE_MAX_RUNES
+		})
+		defer th.App.UpdateConfig(func(cfg *model.Config) {
+			*cfg.TeamSettings.MaxChannelHeaderLength = model.TEAM_SETTINGS_DEFAULT_MAX_CHANNEL_HEADER
+			*cfg.TeamSettings.MaxChannelPurposeLength = model.TEAM_SETTINGS_DEFAULT_MAX_CHANNEL_PURPOSE
+		})
+
+		header := strings.Repeat("h", model.CHANNEL_HEADER_MAX_RUNES)
+		purpose := strings.Repeat("p", model.CHANNEL_PURPOSE_MAX_RUNES)
+		channel, resp := Client.PatchChannel(th.BasicChannel.Id, &model.ChannelPatch{Header: &header, Purpose: &purpose})
+		CheckNoError(t, resp)
+		assert.Equal(t, header, channel.Header)
+		assert.Equal(t, purpose, channel.Purpose)
+
+		_, resp = patchHeader(header + "h")
+		CheckBadRequestStatus(t, resp)
+	})
+
+	t.Run("other changes are allowed after lowering the maximum", func(t *testing.T) {
+		displayName := "Renamed"
+		channel, resp := Client.PatchChannel(th.BasicChannel.Id, &model.ChannelPatch{DisplayName: &displayName})
+		CheckNoError(t, resp)
+		assert.Equal(t, displayName, channel.DisplayName)
+		assert.Len(t, channel.Header, model.CHANNEL_HEADER_MAX_RUNES)
+	})
+
+	t.Run("script links", func(t *testing.T) {
+		_, resp := patchHeader("[runbook](javascript:alert(document.cookie))")
+		CheckBadRequestStatus(t, resp)
+		CheckErrorMessage(t, resp, "model.channel.is_valid.header_link.app_error")
+
+		channel, resp := patchHeader("[runbook](https://wiki.example.com/runbook)")
+		CheckNoError(t, resp)
+		assert.Equal(t, "[runbook](https://wiki.example.com/runbook)", channel.Header)
+	})
+
+	t.Run("only changes are sent to clients", func(t *testing.T) {
+		purpose := "New purpose"
+		channel, resp := Client.PatchChannel(th.BasicChannel.Id, &model.ChannelPatch{Purpose: &purpose})
+		CheckNoError(t, resp)
+
+		timeout := time.After(2 * time.Second)
+		for {
+			select {
+			case event := <-WebSocketClient.EventChannel:
+				if event.Event != model.WEBSOCKET_EVENT_CHANNEL_UPDATED {
+					continue
+				}
+
+				changes := model.StringInterfaceFromJson(strings.NewReader(event.Data["changes"].(string)))
+				if changes["purpose"] != purpose {
+					continue
+				}
+
+				assert.Equal(t, th.BasicChannel.Id, event.Broadcast.ChannelId)
+				assert.Equal(t, map[string]interface{}{"purpose": purpose, "update_at": float64(channel.UpdateAt)}, changes)
+				assert.Nil(t, event.Data["channel"])
+				return
+			case <-timeout:
+				require.FailNow(t, "timed out waiting for the channel updated event")
+			}
+		}
+	})
+}
+
 func TestCreateDirectChannel(t *testing.T) {
 	th := Setup().InitBasic().InitSystemAdmin()
 	defer th.TearDown()
@@ -566,6 +760,25 @@ func TestGetPublicChannelsForTeam(t *testing.T) {
 	CheckNoError(t, resp)
 }
 
+func TestGetPublicChannelsForTeamWithFields(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+	Client := th.Client
+
+	channels, resp := Client.GetPublicChannelsForTeamWithFields(th.BasicTeam.Id, 0, 100, []string{"id", "display_name"})
+	CheckNoError(t, resp)
+	require.NotEmpty(t, channels)
+	for _, channel := range channels {
+		assert.NotEmpty(t, channel.Id)
+		assert.NotEmpty(t, channel.DisplayName)
+		assert.Empty(t, channel.Name)
+		assert.Empty(t, channel.TeamId)
+	}
+
+	_, resp = Client.GetPublicChannelsForTeamWithFields(th.BasicTeam.Id, 0, 100, []string{"id", "enable_feed"})
+	CheckBadRequestStatus(t, resp)
+}
+
 func TestGetPublicChannelsByIdsForTeam(t *testing.T) {
 	th := Setup().InitBasic().InitSystemAdmin()
 	defer th.TearDown()
@@ -941,6 +1154,87 @@ func TestConvertChannelToPrivate(t *testing.T) {
 	}
 }
 
+func TestConvertChannel(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	enabled := *th.App.Config().TeamSettings.EnablePrivateToPublicConversion
+	defer th.App.UpdateConfig(func(cfg *model.Config) { *cfg.TeamSettings.EnablePrivateToPublicConversion = enabled })
+
+	t.Run("to private", func(t *testing.T) {
+		publicChannel := th.CreatePublicChannel()
+
+		_, resp := Client.ConvertChannel(publicChannel.Id, model.CHANNEL_PRIVATE)
+		CheckForbiddenStatus(t, resp)
+
+		conversion, resp := th.SystemAdminClient.ConvertChannel(publicChannel.Id, model.CHANNEL_PRIVATE)
+		CheckOKStatus(t, resp)
+		require.NotNil(t, conversion)
+		assert.Equal(t, publicChannel.Id, conversion.Id)
+		assert.Equal(t, model.CHANNEL_PRIVATE, conversion.Type)
+		require.NotNil(t, conversion.Integrations)
+
+		_, resp = th.SystemAdminClient.ConvertChannel(publicChannel.Id, model.CHANNEL_PRIVATE)
+		CheckBadRequestStatus(t, resp)
+	})
+
+	t.Run("to public", func(t *testing.T) {
+		privateChannel := th.CreatePrivateChannel()
+
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.TeamSettings.EnablePrivateToPublicConversion = false })
+
+		_, resp := th.SystemAdminClient.ConvertChannel(privateChannel.Id, model.CHANNEL_OPEN)
+		CheckNotImplementedStatus(t, resp)
+
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.TeamSettings.EnablePrivateToPublicConversion = true })
+
+		// Team admins can make channels private, but only system admins can make them public
+		th.LoginTeamAdmin()
+		_, resp = Client.ConvertChannel(privateChannel.Id, model.CHANNEL_OPEN)
+		CheckForbiddenStatus(t, resp)
+
+		conversion, resp := th.SystemAdminClient.ConvertChannel(privateChannel.Id, model.CHANNEL_OPEN)
+		CheckOKStatus(t, resp)
+		require.NotNil(t, conversion)
+		assert.Equal(t, model.CHANNEL_OPEN, conversion.Type)
+
+		_, resp = th.SystemAdminClient.ConvertChannel(privateChannel.Id, model.CHANNEL_OPEN)
+		CheckBadRequestStatus(t, resp)
+
+		th.LoginBasic()
+	})
+
+	t.Run("invalid type", func(t *testing.T) {
+		_, resp := th.SystemAdminClient.ConvertChannel(th.BasicChannel.Id, model.CHANNEL_GROUP)
+		CheckBadRequestStatus(t, resp)
+	})
+
+	t.Run("report", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) { cfg.ServiceSettings.EnableIncomingWebhooks = true })
+
+		channel := th.CreatePublicChannel()
+
+		hook, err := th.App.CreateIncomingWebhookForChannel(th.BasicUser.Id, channel, &model.IncomingWebhook{ChannelId: channel.Id})
+		require.Nil(t, err)
+
+		bot := store.Must(th.App.Srv.Store.User().Save(&model.User{Email: th.GenerateTestEmail(), Username: "bot" + model.NewId(), IsBot: true})).(*model.User)
+		th.LinkUserToTeam(bot, th.BasicTeam)
+		th.AddUserToChannel(bot, channel)
+
+		conversion, resp := th.SystemAdminClient.ConvertChannel(channel.Id, model.CHANNEL_PRIVATE)
+		CheckOKStatus(t, resp)
+		require.NotNil(t, conversion.Integrations)
+
+		require.Len(t, conversion.Integrations.IncomingWebhooks, 1)
+		assert.Equal(t, hook.Id, conversion.Integrations.IncomingWebhooks[0].Id)
+
+		require.Len(t, conversion.Integrations.Bots, 1)
+		assert.Equal(t, bot.Id, conversion.Integrations.Bots[0].Id)
+		assert.Equal(t, "", conversion.Integrations.Bots[0].Password)
+	})
+}
+
 func TestRestoreChannel(t *testing.T) {
 	th := Setup().InitBasic().InitSystemAdmin()
 	defer th.TearDown()
@@ -967,6 +1261,51 @@ func TestRestoreChannel(t *testing.T) {
 	CheckOKStatus(t, resp)
 }
 
+func TestPermanentDeleteChannelWithGracePeriod(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.ServiceSettings.EnableAPIChannelDeletion = true
+		*cfg.ServiceSettings.ChannelDeletionGracePeriodDays = 7
+	})
+
+	publicChannel1 := th.CreatePublicChannel()
+
+	_, resp := Client.PermanentDeleteChannel(publicChannel1.Id)
+	CheckForbiddenStatus(t, resp)
+
+	pass, resp := th.SystemAdminClient.PermanentDeleteChannel(publicChannel1.Id)
+	CheckNoError(t, resp)
+	require.True(t, pass)
+
+	channel, err := th.App.GetChannel(publicChannel1.Id)
+	require.Nil(t, err)
+	assert.NotEqual(t, int64(0), channel.DeleteAt)
+	assert.NotEqual(t, int64(0), channel.PermanentDeleteAt)
+
+	th.LoginTeamAdmin()
+	_, resp = Client.RestoreChannel(publicChannel1.Id)
+	CheckForbiddenStatus(t, resp)
+
+	restored, resp := th.SystemAdminClient.RestoreChannel(publicChannel1.Id)
+	CheckNoError(t, resp)
+	assert.Equal(t, int64(0), restored.DeleteAt)
+	assert.Equal(t, int64(0), restored.PermanentDeleteAt)
+
+	// Without the setting, permanent deletes only archive the channel
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableAPIChannelDeletion = false })
+
+	_, resp = th.SystemAdminClient.PermanentDeleteChannel(publicChannel1.Id)
+	CheckNoError(t, resp)
+
+	channel, err = th.App.GetChannel(publicChannel1.Id)
+	require.Nil(t, err)
+	assert.NotEqual(t, int64(0), channel.DeleteAt)
+	assert.Equal(t, int64(0), channel.PermanentDeleteAt)
+}
+
 func TestGetChannelByName(t *testing.T) {
 	th := Setup().InitBasic().InitSystemAdmin()
 	defer th.TearDown()
@@ -1016,6 +1355,76 @@ func TestGetChannelByName(t *testing.T) {
 	CheckNoError(t, resp)
 }
 
+func TestGetDirectChannelByUsername(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.TeamSettings.TeammateNameDisplay = model.SHOW_USERNAME })
+
+	dm, resp := Client.CreateDirectChannel(th.BasicUser.Id, th.BasicUser2.Id)
+	CheckNoError(t, resp)
+
+	channel, resp := Client.GetChannelByName("@"+th.BasicUser2.Username, th.BasicTeam.Id, "")
+	CheckNoError(t, resp)
+	assert.Equal(t, dm.Id, channel.Id)
+	assert.Equal(t, th.BasicUser2.Username, channel.DirectDisplayName)
+
+	oldUsername := th.BasicUser2.Username
+	_, resp = th.SystemAdminClient.PatchUser(th.BasicUser2.Id, &model.UserPatch{Username: model.NewString("renamed" + model.NewId())})
+	CheckNoError(t, resp)
+
+	channel, resp = Client.GetChannelByName("@"+oldUsername, th.BasicTeam.Id, "")
+	CheckNoError(t, resp)
+	assert.Equal(t, dm.Id, channel.Id)
+	assert.Equal(t, "@"+oldUsername, channel.RedirectedFrom)
+
+	channel, resp = Client.GetChannel(dm.Id, "")
+	CheckNoError(t, resp)
+	assert.NotEqual(t, oldUsername, channel.DirectDisplayName)
+	assert.NotEmpty(t, channel.DirectDisplayName)
+
+	_, resp = Client.GetChannelByName("@"+th.CreateUser().Username, th.BasicTeam.Id, "")
+	CheckNotFoundStatus(t, resp)
+
+	Client.Logout()
+	_, resp = Client.GetChannelByName("@"+oldUsername, th.BasicTeam.Id, "")
+	CheckUnauthorizedStatus(t, resp)
+}
+
+func TestGetDirectChannelWithUser(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+	Client := th.Client
+
+	_, resp := Client.GetDirectChannelWithUser(th.BasicUser2.Id, false)
+	CheckNotFoundStatus(t, resp)
+
+	created, resp := Client.GetDirectChannelWithUser(th.BasicUser2.Id, true)
+	CheckNoError(t, resp)
+	assert.Equal(t, model.CHANNEL_DIRECT, created.Type)
+	assert.Equal(t, model.GetDMNameFromIds(th.BasicUser.Id, th.BasicUser2.Id), created.Name)
+
+	channel, resp := Client.GetDirectChannelWithUser(th.BasicUser2.Id, false)
+	CheckNoError(t, resp)
+	assert.Equal(t, created.Id, channel.Id)
+	assert.Equal(t, th.BasicUser2.GetDisplayName(*th.App.Config().TeamSettings.TeammateNameDisplay), channel.DirectDisplayName)
+
+	th.RemovePermissionFromRole(model.PERMISSION_CREATE_DIRECT_CHANNEL.Id, model.SYSTEM_USER_ROLE_ID)
+	defer th.AddPermissionToRole(model.PERMISSION_CREATE_DIRECT_CHANNEL.Id, model.SYSTEM_USER_ROLE_ID)
+
+	other := th.CreateUser()
+	_, resp = Client.GetDirectChannelWithUser(other.Id, true)
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = Client.GetDirectChannelWithUser(th.BasicUser2.Id, false)
+	CheckNoError(t, resp)
+
+	Client.Logout()
+	_, resp = Client.GetDirectChannelWithUser(th.BasicUser2.Id, false)
+	CheckUnauthorizedStatus(t, resp)
+}
+
 func TestGetChannelByNameForTeamName(t *testing.T) {
 	th := Setup().InitBasic().InitSystemAdmin()
 	defer th.TearDown()
@@ -1354,6 +1763,199 @@ func TestGetChannelUnread(t *testing.T) {
 	CheckNotFoundStatus(t, resp)
 }
 
+func TestGetChannelUnreadsForUser(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+	user := th.BasicUser
+
+	other := th.CreatePublicChannel()
+	dm, resp := Client.CreateDirectChannel(user.Id, th.BasicUser2.Id)
+	CheckNoError(t, resp)
+
+	Client2 := th.CreateClient()
+	th.LoginBasic2WithClient(Client2)
+	th.App.AddUserToChannel(th.BasicUser2, other)
+
+	th.CreateMessagePostWithClient(Client2, th.BasicChannel, "regular post")
+	th.CreateMessagePostWithClient(Client2, th.BasicChannel, "hi @"+user.Username)
+	th.CreateMessagePostWithClient(Client2, other, "another regular post")
+	th.CreateMessagePostWithClient(Client2, dm, "direct message")
+
+	byChannel := func(unreads []*model.ChannelUnread) map[string]*model.ChannelUnread {
+		m := make(map[string]*model.ChannelUnread)
+		for _, unread := range unreads {
+			m[unread.ChannelId] = unread
+		}
+		return m
+	}
+
+	unreads, resp := Client.GetChannelUnreadsForTeamForUser(th.BasicTeam.Id, user.Id)
+	CheckNoError(t, resp)
+	byId := byChannel(unreads)
+	require.Contains(t, byId, th.BasicChannel.Id)
+	require.Contains(t, byId, other.Id)
+	require.Contains(t, byId, dm.Id, "direct messages are included for every team")
+	assert.Equal(t, int64(2), byId[th.BasicChannel.Id].MsgCount)
+	assert.Equal(t, int64(1), byId[th.BasicChannel.Id].MentionCount)
+	assert.Equal(t, int64(1), byId[other.Id].MsgCount)
+	assert.Equal(t, int64(0), byId[other.Id].MentionCount)
+	assert.Equal(t, int64(1), byId[dm.Id].MsgCount)
+	assert.Equal(t, int64(1), byId[dm.Id].MentionCount)
+
+	// only mentions are counted for channels that are only marked unread for mentions
+	_, resp = Client.UpdateChannelNotifyProps(other.Id, user.Id, map[string]string{model.MARK_UNREAD_NOTIFY_PROP: model.CHANNEL_MARK_UNREAD_MENTION})
+	CheckNoError(t, resp)
+
+	WebSocketClient, err := th.CreateWebSocketClient()
+	require.Nil(t, err)
+	WebSocketClient.Listen()
+	defer WebSocketClient.Close()
+
+	_, resp = Client.ViewChannel(user.Id, &model.ChannelView{ChannelId: th.BasicChannel.Id})
+	CheckNoError(t, resp)
+
+	timeout := time.After(2 * time.Second)
+	var viewed *model.WebSocketEvent
+	for viewed == nil {
+		select {
+		case event := <-WebSocketClient.EventChannel:
+			if event.Event == model.WEBSOCKET_EVENT_CHANNEL_VIEWED && event.Data["channel_id"] == th.BasicChannel.Id {
+				viewed = event
+			}
+		case <-timeout:
+			require.FailNow(t, "timed out waiting for the channel viewed event")
+		}
+	}
+	assert.EqualValues(t, 0, viewed.Data["msg_count"])
+	assert.EqualValues(t, 0, viewed.Data["mention_count"])
+	assert.NotZero(t, viewed.Data["last_viewed_at"])
+
+	unreads, resp = Client.GetChannelUnreadsForUser(user.Id)
+	CheckNoError(t, resp)
+	byId = byChannel(unreads)
+	assert.Equal(t, int64(0), byId[th.BasicChannel.Id].MsgCount)
+	assert.Equal(t, int64(0), byId[th.BasicChannel.Id].MentionCount)
+	assert.EqualValues(t, viewed.Data["last_viewed_at"], byId[th.BasicChannel.Id].LastViewedAt)
+	assert.Equal(t, int64(0), byId[other.Id].MsgCount)
+	assert.Equal(t, int64(1), byId[dm.Id].MsgCount)
+
+	th.CreateMessagePostWithClient(Client2, th.BasicChannel, "after viewing")
+
+	unreads, resp = Client.GetChannelUnreadsForTeamForUser(th.BasicTeam.Id, user.Id)
+	CheckNoError(t, resp)
+	assert.Equal(t, int64(1), byChannel(unreads)[th.BasicChannel.Id].MsgCount)
+	assert.Equal(t, int64(0), byChannel(unreads)[th.BasicChannel.Id].MentionCount)
+
+	_, resp = Client.GetChannelUnreadsForUser(th.BasicUser2.Id)
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = Client.GetChannelUnreadsForTeamForUser(model.NewId(), user.Id)
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = th.SystemAdminClient.GetChannelUnreadsForUser(user.Id)
+	CheckNoError(t, resp)
+
+	Client.Logout()
+	_, resp = Client.GetChannelUnreadsForUser(user.Id)
+	CheckUnauthorizedStatus(t, resp)
+}
+
+func TestMarkChannelsAsRead(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+	user := th.BasicUser
+
+	otherTeam := th.CreateTeam()
+	th.LinkUserToTeam(th.BasicUser2, otherTeam)
+	otherTeamChannel := th.CreateChannelWithClientAndTeam(Client, model.CHANNEL_OPEN, otherTeam.Id)
+	th.AddUserToChannel(th.BasicUser2, otherTeamChannel)
+
+	other := th.CreatePublicChannel()
+	th.AddUserToChannel(th.BasicUser2, other)
+	dm, resp := Client.CreateDirectChannel(user.Id, th.BasicUser2.Id)
+	CheckNoError(t, resp)
+
+	Client2 := th.CreateClient()
+	th.LoginBasic2WithClient(Client2)
+
+	th.CreateMessagePostWithClient(Client2, th.BasicChannel, "hi @"+user.Username)
+	th.CreateMessagePostWithClient(Client2, other, "regular post")
+	th.CreateMessagePostWithClient(Client2, dm, "direct message")
+	th.CreateMessagePostWithClient(Client2, otherTeamChannel, "@"+user.Username+" on another team")
+
+	unreadsById := func(unreads []*model.ChannelUnread) map[string]*model.ChannelUnread {
+		m := make(map[string]*model.ChannelUnread)
+		for _, unread := range unreads {
+			m[unread.ChannelId] = unread
+		}
+		return m
+	}
+
+	WebSocketClient, err := th.CreateWebSocketClient()
+	require.Nil(t, err)
+	WebSocketClient.Listen()
+	defer WebSocketClient.Close()
+
+	channelIds, resp := Client.MarkChannelsAsReadForTeamForUser(th.BasicTeam.Id, user.Id)
+	CheckNoError(t, resp)
+	assert.ElementsMatch(t, []string{th.BasicChannel.Id, other.Id, dm.Id}, channelIds)
+
+	var viewed []*model.WebSocketEvent
+	timeout := time.After(time.Second)
+	for waiting := true; waiting; {
+		select {
+		case event := <-WebSocketClient.EventChannel:
+			if event.Event == model.WEBSOCKET_EVENT_MULTIPLE_CHANNELS_VIEWED || event.Event == model.WEBSOCKET_EVENT_CHANNEL_VIEWED {
+				viewed = append(viewed, event)
+			}
+		case <-timeout:
+			waiting = false
+		}
+	}
+	require.Len(t, viewed, 1, "a single event should be sent for all of the channels")
+	assert.Equal(t, model.WEBSOCKET_EVENT_MULTIPLE_CHANNELS_VIEWED, viewed[0].Event)
+	assert.Equal(t, th.BasicTeam.Id, viewed[0].Data["team_id"])
+	assert.ElementsMatch(t, channelIds, model.ArrayFromJson(strings.NewReader(viewed[0].Data["channel_ids"].(string))))
+
+	unreads, resp := Client.GetChannelUnreadsForUser(user.Id)
+	CheckNoError(t, resp)
+	byId := unreadsById(unreads)
+	for _, channelId := range channelIds {
+		assert.Equal(t, int64(0), byId[channelId].MsgCount)
+		assert.Equal(t, int64(0), byId[channelId].MentionCount)
+	}
+	assert.Equal(t, int64(1), byId[otherTeamChannel.Id].MsgCount, "channels on other teams shouldn't be marked as read")
+	assert.Equal(t, int64(1), byId[otherTeamChannel.Id].MentionCount)
+
+	channelIds, resp = Client.MarkChannelsAsReadForUser(user.Id)
+	CheckNoError(t, resp)
+	assert.Equal(t, []string{otherTeamChannel.Id}, channelIds)
+
+	unreads, resp = Client.GetChannelUnreadsForTeamForUser(otherTeam.Id, user.Id)
+	CheckNoError(t, resp)
+	assert.Equal(t, int64(0), unreadsById(unreads)[otherTeamChannel.Id].MsgCount)
+	assert.Equal(t, int64(0), unreadsById(unreads)[otherTeamChannel.Id].MentionCount)
+
+	channelIds, resp = Client.MarkChannelsAsReadForUser(user.Id)
+	CheckNoError(t, resp)
+	assert.Empty(t, channelIds)
+
+	_, resp = Client.MarkChannelsAsReadForUser(th.BasicUser2.Id)
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = Client.MarkChannelsAsReadForTeamForUser(model.NewId(), user.Id)
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = th.SystemAdminClient.MarkChannelsAsReadForTeamForUser(th.BasicTeam.Id, user.Id)
+	CheckNoError(t, resp)
+
+	Client.Logout()
+	_, resp = Client.MarkChannelsAsReadForUser(user.Id)
+	CheckUnauthorizedStatus(t, resp)
+}
+
 func TestGetChannelStats(t *testing.T) {
 	th := Setup().InitBasic().InitSystemAdmin()
 	defer th.TearDown()
@@ -1506,6 +2108,101 @@ func TestUpdateChannelRoles(t *testing.T) {
 	CheckForbiddenStatus(t, resp)
 }
 
+// waitForChannelMemberUpdated returns the channel_member_updated event for the user in the channel, or fails the test
+// if it isn't received in time.
+func waitForChannelMemberUpdated(t *testing.T, client *model.WebSocketClient, channelId, userId string) *model.WebSocketEvent {
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case event := <-client.EventChannel:
+			if event.Event == model.WEBSOCKET_EVENT_CHANNEL_MEMBER_UPDATED && event.Broadcast.ChannelId == channelId && event.Data["user_id"] == userId {
+				return event
+			}
+		case <-timeout:
+			require.FailNow(t, "timed out waiting for the channel member updated event")
+			return nil
+		}
+	}
+}
+
+func TestUpdateChannelRolesWebSocketEvents(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	const CHANNEL_ADMIN = "channel_admin channel_user"
+
+	channel := th.CreatePublicChannel()
+	th.App.AddUserToChannel(th.BasicUser2, channel)
+	th.App.AddUserToChannel(th.SystemAdminUser, channel)
+
+	Client2 := th.CreateClient()
+	th.LoginBasic2WithClient(Client2)
+
+	targetClient, err := model.NewWebSocketClient4(fmt.Sprintf("ws://localhost:%v", th.App.Srv.ListenAddr.Port), Client2.AuthToken)
+	require.Nil(t, err)
+	targetClient.Listen()
+	defer targetClient.Close()
+
+	otherClient, err := model.NewWebSocketClient4(fmt.Sprintf("ws://localhost:%v", th.App.Srv.ListenAddr.Port), th.SystemAdminClient.AuthToken)
+	require.Nil(t, err)
+	otherClient.Listen()
+	defer otherClient.Close()
+
+	t.Run("single member", func(t *testing.T) {
+		_, resp := Client.UpdateChannelRoles(channel.Id, th.BasicUser2.Id, CHANNEL_ADMIN)
+		CheckNoError(t, resp)
+
+		for _, client := range []*model.WebSocketClient{targetClient, otherClient} {
+			event := waitForChannelMemberUpdated(t, client, channel.Id, th.BasicUser2.Id)
+			assert.Equal(t, th.BasicUser.Id, event.Data["actor_id"])
+
+			member := model.ChannelMemberFromJson(strings.NewReader(event.Data["channelMember"].(string)))
+			require.NotNil(t, member)
+			assert.Equal(t, CHANNEL_ADMIN, member.Roles)
+		}
+
+		// The promoted user's permissions should change without waiting for the cache to expire
+		_, resp = Client2.UpdateChannelRoles(channel.Id, th.SystemAdminUser.Id, CHANNEL_ADMIN)
+		CheckNoError(t, resp)
+		waitForChannelMemberUpdated(t, targetClient, channel.Id, th.SystemAdminUser.Id)
+	})
+
+	t.Run("bulk", func(t *testing.T) {
+		userIds := []string{th.BasicUser2.Id, th.SystemAdminUser.Id}
+
+		pass, resp := Client.UpdateChannelMembersRoles(channel.Id, userIds, model.CHANNEL_USER_ROLE_ID)
+		CheckNoError(t, resp)
+		assert.True(t, pass)
+
+		for _, userId := range userIds {
+			waitForChannelMemberUpdated(t, targetClient, channel.Id, userId)
+			waitForChannelMemberUpdated(t, otherClient, channel.Id, userId)
+
+			member, resp := Client.GetChannelMember(channel.Id, userId, "")
+			CheckNoError(t, resp)
+			assert.Equal(t, model.CHANNEL_USER_ROLE_ID, member.Roles)
+		}
+
+		// Nobody's roles change if any of the users isn't a member
+		_, resp = Client.UpdateChannelMembersRoles(channel.Id, []string{th.BasicUser2.Id, model.NewId()}, CHANNEL_ADMIN)
+		CheckNotFoundStatus(t, resp)
+
+		member, resp := Client.GetChannelMember(channel.Id, th.BasicUser2.Id, "")
+		CheckNoError(t, resp)
+		assert.Equal(t, model.CHANNEL_USER_ROLE_ID, member.Roles)
+
+		_, resp = Client.UpdateChannelMembersRoles(channel.Id, []string{}, CHANNEL_ADMIN)
+		CheckBadRequestStatus(t, resp)
+
+		_, resp = Client.UpdateChannelMembersRoles(channel.Id, userIds, "junk")
+		CheckBadRequestStatus(t, resp)
+
+		_, resp = Client2.UpdateChannelMembersRoles(channel.Id, userIds, CHANNEL_ADMIN)
+		CheckForbiddenStatus(t, resp)
+	})
+}
+
 func TestUpdateChannelNotifyProps(t *testing.T) {
 	th := Setup().InitBasic().InitSystemAdmin()
 	defer th.TearDown()
@@ -1556,6 +2253,94 @@ func TestUpdateChannelNotifyProps(t *testing.T) {
 	CheckNoError(t, resp)
 }
 
+func TestUpdateChannelMembersNotifyProps(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+	user := th.BasicUser
+	other := th.CreatePublicChannel()
+
+	WebSocketClient, err := th.CreateWebSocketClient()
+	require.Nil(t, err)
+	WebSocketClient.Listen()
+	defer WebSocketClient.Close()
+
+	pass, resp := Client.UpdateChannelMembersNotifyProps(user.Id, model.ChannelNotifyPropsByChannel{
+		th.BasicChannel.Id: {model.DESKTOP_NOTIFY_PROP: model.CHANNEL_NOTIFY_MENTION},
+		other.Id:           {model.PUSH_NOTIFY_PROP: model.CHANNEL_NOTIFY_NONE, model.MARK_UNREAD_NOTIFY_PROP: model.CHANNEL_MARK_UNREAD_MENTION},
+	})
+	CheckNoError(t, resp)
+	require.True(t, pass)
+
+	member, err := th.App.GetChannelMember(th.BasicChannel.Id, user.Id)
+	require.Nil(t, err)
+	assert.Equal(t, model.CHANNEL_NOTIFY_MENTION, member.NotifyProps[model.DESKTOP_NOTIFY_PROP])
+	assert.Equal(t, model.CHANNEL_MARK_UNREAD_ALL, member.NotifyProps[model.MARK_UNREAD_NOTIFY_PROP], "props that aren't given should be left alone")
+
+	member, err = th.App.GetChannelMember(other.Id, user.Id)
+	require.Nil(t, err)
+	assert.Equal(t, model.CHANNEL_NOTIFY_NONE, member.NotifyProps[model.PUSH_NOTIFY_PROP])
+	assert.Equal(t, model.CHANNEL_MARK_UNREAD_MENTION, member.NotifyProps[model.MARK_UNREAD_NOTIFY_PROP])
+
+	timeout := time.After(2 * time.Second)
+	var updated *model.WebSocketEvent
+	for updated == nil {
+		select {
+		case event := <-WebSocketClient.EventChannel:
+			if event.Event == model.WEBSOCKET_EVENT_CHANNEL_MEMBERS_UPDATED {
+				updated = event
+			}
+		case <-timeout:
+			require.FailNow(t, "timed out waiting for the channel members updated event")
+		}
+	}
+	members := model.ChannelMembersFromJson(strings.NewReader(updated.Data["channelMembers"].(string)))
+	require.NotNil(t, members)
+	assert.Len(t, *members, 2, "both memberships should be sent in one event")
+
+	t.Run("invalid values change nothing", func(t *testing.T) {
+		_, resp := Client.UpdateChannelMembersNotifyProps(user.Id, model.ChannelNotifyPropsByChannel{
+			th.BasicChannel.Id: {model.DESKTOP_NOTIFY_PROP: model.CHANNEL_NOTIFY_ALL},
+			other.Id:           {model.PUSH_NOTIFY_PROP: "loudly"},
+		})
+		CheckBadRequestStatus(t, resp)
+		CheckErrorMessage(t, resp, "model.channel_member.notify_props.invalid_value.app_error")
+
+		member, err := th.App.GetChannelMember(th.BasicChannel.Id, user.Id)
+		require.Nil(t, err)
+		assert.Equal(t, model.CHANNEL_NOTIFY_MENTION, member.NotifyProps[model.DESKTOP_NOTIFY_PROP])
+
+		_, resp = Client.UpdateChannelMembersNotifyProps(user.Id, model.ChannelNotifyPropsByChannel{
+			th.BasicChannel.Id: {"volume": "loud"},
+		})
+		CheckBadRequestStatus(t, resp)
+		CheckErrorMessage(t, resp, "model.channel_member.notify_props.unknown.app_error")
+
+		_, resp = Client.UpdateChannelMembersNotifyProps(user.Id, model.ChannelNotifyPropsByChannel{
+			"junk": {model.DESKTOP_NOTIFY_PROP: model.CHANNEL_NOTIFY_ALL},
+		})
+		CheckBadRequestStatus(t, resp)
+	})
+
+	t.Run("channels the user isn't in", func(t *testing.T) {
+		_, resp := Client.UpdateChannelMembersNotifyProps(user.Id, model.ChannelNotifyPropsByChannel{
+			th.BasicChannel.Id: {model.DESKTOP_NOTIFY_PROP: model.CHANNEL_NOTIFY_ALL},
+			model.NewId():      {model.DESKTOP_NOTIFY_PROP: model.CHANNEL_NOTIFY_ALL},
+		})
+		CheckNotFoundStatus(t, resp)
+	})
+
+	t.Run("other users", func(t *testing.T) {
+		props := model.ChannelNotifyPropsByChannel{th.BasicChannel.Id: {model.DESKTOP_NOTIFY_PROP: model.CHANNEL_NOTIFY_ALL}}
+
+		_, resp := Client.UpdateChannelMembersNotifyProps(th.BasicUser2.Id, props)
+		CheckForbiddenStatus(t, resp)
+
+		_, resp = th.SystemAdminClient.UpdateChannelMembersNotifyProps(user.Id, props)
+		CheckNoError(t, resp)
+	})
+}
+
 func TestAddChannelMember(t *testing.T) {
 	th := Setup().InitBasic().InitSystemAdmin()
 	defer th.TearDown()
@@ -1715,6 +2500,101 @@ func TestAddChannelMember(t *testing.T) {
 	Client.Logout()
 }
 
+func TestGroupChannelMembers(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+	Client := th.Client
+	user := th.BasicUser
+	user2 := th.BasicUser2
+	user3 := th.CreateUser()
+	user4 := th.CreateUser()
+
+	channel, resp := Client.CreateGroupChannel([]string{user.Id, user2.Id, user3.Id})
+	CheckNoError(t, resp)
+	post := th.CreatePostWithClient(Client, channel)
+
+	updated, resp := Client.AddGroupChannelMembers(channel.Id, []string{user4.Id})
+	CheckNoError(t, resp)
+	assert.Equal(t, channel.Id, updated.Id, "the channel should keep its id so that its history is kept")
+	assert.Equal(t, model.GetGroupNameFromUserIds([]string{user.Id, user2.Id, user3.Id, user4.Id}), updated.Name)
+
+	_, resp = Client.AddGroupChannelMembers(channel.Id, []string{})
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = Client.AddGroupChannelMembers(th.BasicChannel.Id, []string{user4.Id})
+	CheckBadRequestStatus(t, resp)
+
+	var userIds []string
+	for i := 0; i < model.CHANNEL_GROUP_MAX_USERS-3; i++ {
+		userIds = append(userIds, th.CreateUser().Id)
+	}
+	_, resp = Client.AddGroupChannelMembers(channel.Id, userIds)
+	CheckBadRequestStatus(t, resp)
+
+	// a group channel with the same members as another can't be made by changing its members
+	other, resp := Client.CreateGroupChannel([]string{user.Id, user2.Id, user4.Id})
+	CheckNoError(t, resp)
+	_, resp = Client.AddGroupChannelMembers(other.Id, []string{user3.Id})
+	CheckBadRequestStatus(t, resp)
+
+	updated, resp = Client.RemoveGroupChannelMembers(channel.Id, []string{user2.Id})
+	CheckNoError(t, resp)
+	assert.Equal(t, model.GetGroupNameFromUserIds([]string{user.Id, user3.Id, user4.Id}), updated.Name)
+
+	// removed members can't read the channel by default
+	th.LoginBasic2()
+	_, resp = Client.GetPostsForChannel(channel.Id, 0, 60, "")
+	CheckForbiddenStatus(t, resp)
+	_, resp = Client.GetPost(post.Id, "")
+	CheckForbiddenStatus(t, resp)
+
+	// nor can they change its members
+	_, resp = Client.AddGroupChannelMembers(channel.Id, []string{user2.Id})
+	CheckForbiddenStatus(t, resp)
+
+	// but they can read what was posted before they were removed if that's allowed
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.TeamSettings.EnableGroupChannelRemovedMemberHistory = true })
+	defer th.App.UpdateConfig(func(cfg *model.Config) { *cfg.TeamSettings.EnableGroupChannelRemovedMemberHistory = false })
+
+	th.LoginBasic()
+	laterPost := th.CreatePostWithClient(Client, channel)
+
+	th.LoginBasic2()
+	posts, resp := Client.GetPostsForChannel(channel.Id, 0, 60, "")
+	CheckNoError(t, resp)
+	assert.Contains(t, posts.Posts, post.Id)
+	assert.NotContains(t, posts.Posts, laterPost.Id)
+
+	_, resp = Client.GetPost(post.Id, "")
+	CheckNoError(t, resp)
+	_, resp = Client.GetPost(laterPost.Id, "")
+	CheckForbiddenStatus(t, resp)
+
+	// only the creator can change the members when it's restricted to them
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.TeamSettings.RestrictGroupChannelManageMembers = model.GROUP_CHANNEL_MANAGE_MEMBERS_CREATOR
+	})
+	defer th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.TeamSettings.RestrictGroupChannelManageMembers = model.GROUP_CHANNEL_MANAGE_MEMBERS_ANY
+	})
+
+	Client.Login(user3.Email, user3.Password)
+	_, resp = Client.RemoveGroupChannelMembers(channel.Id, []string{user4.Id})
+	CheckForbiddenStatus(t, resp)
+
+	// anyone can still leave, which renames the channel too
+	_, resp = Client.RemoveUserFromChannel(channel.Id, user3.Id)
+	CheckNoError(t, resp)
+
+	th.LoginBasic()
+	updated, resp = Client.GetChannel(channel.Id, "")
+	CheckNoError(t, resp)
+	assert.Equal(t, model.GetGroupNameFromUserIds([]string{user.Id, user4.Id}), updated.Name)
+
+	_, resp = Client.RemoveGroupChannelMembers(channel.Id, []string{user.Id, user4.Id})
+	CheckBadRequestStatus(t, resp)
+}
+
 func TestRemoveChannelMember(t *testing.T) {
 	th := Setup().InitBasic().InitSystemAdmin()
 	user1 := th.BasicUser
@@ -1812,6 +2692,58 @@ func TestRemoveChannelMember(t *testing.T) {
 	CheckNoError(t, resp)
 }
 
+func TestGetChannelMemberHistory(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	channel := th.CreatePublicChannel()
+	since := model.GetMillis() - 1000
+
+	_, resp := Client.AddChannelMember(channel.Id, th.BasicUser2.Id)
+	CheckNoError(t, resp)
+	_, resp = Client.RemoveUserFromChannel(channel.Id, th.BasicUser2.Id)
+	CheckNoError(t, resp)
+
+	_, resp = Client.GetChannelMemberHistory(channel.Id, since, 0, 0, 60)
+	CheckForbiddenStatus(t, resp)
+
+	histories, resp := th.SystemAdminClient.GetChannelMemberHistory(channel.Id, since, 0, 0, 60)
+	CheckNoError(t, resp)
+	if len(histories) != 2 {
+		t.Fatal("should have returned the creator and the removed user")
+	}
+	if histories[0].UserId != th.BasicUser2.Id || histories[1].UserId != th.BasicUser.Id {
+		t.Fatal("should have returned the most recently joined first")
+	}
+	if histories[0].JoinActorId != th.BasicUser.Id || histories[0].LeaveActorId != th.BasicUser.Id {
+		t.Fatal("should have recorded who added and removed the user")
+	}
+	if histories[0].LeaveReason != model.CHANNEL_MEMBER_LEAVE_REASON_REMOVED {
+		t.Fatal("wrong leave reason", histories[0].LeaveReason)
+	}
+
+	histories, resp = th.SystemAdminClient.GetChannelMemberHistory(channel.Id, since, 0, 1, 1)
+	CheckNoError(t, resp)
+	if len(histories) != 1 || histories[0].UserId != th.BasicUser.Id {
+		t.Fatal("should have returned the second page")
+	}
+
+	histories, resp = th.SystemAdminClient.GetChannelMemberHistory(channel.Id, 0, since-1000, 0, 60)
+	CheckNoError(t, resp)
+	if len(histories) != 0 {
+		t.Fatal("should not have returned anything before the channel was created")
+	}
+
+	if _, err := th.SystemAdminClient.DoApiGet(th.SystemAdminClient.GetChannelMembersRoute(channel.Id)+"/history?since=junk", ""); err == nil || err.StatusCode != http.StatusBadRequest {
+		t.Fatal("should have failed with an invalid since")
+	}
+
+	Client.Logout()
+	_, resp = Client.GetChannelMemberHistory(channel.Id, since, 0, 0, 60)
+	CheckUnauthorizedStatus(t, resp)
+}
+
 func TestAutocompleteChannels(t *testing.T) {
 	th := Setup().InitBasic()
 	defer th.TearDown()
@@ -1883,3 +2815,119 @@ func TestAutocompleteChannels(t *testing.T) {
 		}
 	}
 }
+
+func TestChannelFeed(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	channel := th.CreatePublicChannel()
+	_, resp := Client.PatchChannel(channel.Id, &model.ChannelPatch{EnableFeed: model.NewBool(true)})
+	CheckNotImplementedStatus(t, resp)
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableChannelFeeds = true })
+
+	private := th.CreatePrivateChannel()
+	_, resp = Client.PatchChannel(private.Id, &model.ChannelPatch{EnableFeed: model.NewBool(true)})
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = Client.GetChannelFeed(channel.Id)
+	CheckNotFoundStatus(t, resp)
+
+	patched, resp := Client.PatchChannel(channel.Id, &model.ChannelPatch{EnableFeed: model.NewBool(true)})
+	CheckNoError(t, resp)
+	require.True(t, patched.EnableFeed)
+	require.Empty(t, patched.FeedKey, "the feed key shouldn't be sent to clients")
+
+	post := th.CreatePostWithClient(Client, channel)
+
+	feed, resp := Client.GetChannelFeed(channel.Id)
+	CheckNoError(t, resp)
+	require.NotEmpty(t, feed.Token)
+	assert.Equal(t, channel.Id, feed.ChannelId)
+	assert.Contains(t, feed.Url, "/api/v4/channels/"+channel.Id+"/feed.atom?token=")
+
+	anonymous := th.CreateClient()
+
+	_, resp = anonymous.GetChannelFeedAtom(channel.Id, "", "")
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = anonymous.GetChannelFeedAtom(channel.Id, feed.Token+"x", "")
+	CheckForbiddenStatus(t, resp)
+
+	data, resp := anonymous.GetChannelFeedAtom(channel.Id, feed.Token, "")
+	CheckNoError(t, resp)
+	assert.Equal(t, model.CHANNEL_FEED_CONTENT_TYPE, resp.Header.Get("Content-Type"))
+	assert.Equal(t, "max-age=300, public", resp.Header.Get("Cache-Control"))
+	assert.NotEmpty(t, resp.Header.Get("Last-Modified"))
+	require.NotEmpty(t, resp.Etag)
+	assert.Contains(t, string(data), "<feed xmlns=\"http://www.w3.org/2005/Atom\">")
+	assert.Contains(t, string(data), post.Message)
+
+	_, resp = anonymous.GetChannelFeedAtom(channel.Id, feed.Token, resp.Etag)
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	t.Run("regenerate token", func(t *testing.T) {
+		regenerated, resp := Client.RegenerateChannelFeedToken(channel.Id)
+		CheckNoError(t, resp)
+		require.NotEqual(t, feed.Token, regenerated.Token)
+
+		_, resp = anonymous.GetChannelFeedAtom(channel.Id, feed.Token, "")
+		CheckForbiddenStatus(t, resp)
+
+		_, resp = anonymous.GetChannelFeedAtom(channel.Id, regenerated.Token, "")
+		CheckNoError(t, resp)
+
+		feed = regenerated
+	})
+
+	t.Run("permissions", func(t *testing.T) {
+		other := th.CreateUser()
+		th.LinkUserToTeam(other, th.BasicTeam)
+		otherClient := th.CreateClient()
+		otherClient.Login(other.Email, other.Password)
+
+		_, resp := otherClient.GetChannelFeed(channel.Id)
+		CheckForbiddenStatus(t, resp)
+
+		_, resp = otherClient.RegenerateChannelFeedToken(channel.Id)
+		CheckForbiddenStatus(t, resp)
+
+		_, resp = th.SystemAdminClient.GetChannelFeed(channel.Id)
+		CheckNoError(t, resp)
+	})
+
+	t.Run("disable feed", func(t *testing.T) {
+		channel := th.CreatePublicChannel()
+		_, resp := Client.PatchChannel(channel.Id, &model.ChannelPatch{EnableFeed: model.NewBool(true)})
+		CheckNoError(t, resp)
+		channelFeed, resp := Client.GetChannelFeed(channel.Id)
+		CheckNoError(t, resp)
+
+		_, resp = Client.PatchChannel(channel.Id, &model.ChannelPatch{EnableFeed: model.NewBool(false)})
+		CheckNoError(t, resp)
+
+		_, resp = anonymous.GetChannelFeedAtom(channel.Id, channelFeed.Token, "")
+		CheckForbiddenStatus(t, resp)
+	})
+
+	t.Run("convert to private", func(t *testing.T) {
+		_, resp := th.SystemAdminClient.ConvertChannelToPrivate(channel.Id)
+		CheckNoError(t, resp)
+
+		_, resp = anonymous.GetChannelFeedAtom(channel.Id, feed.Token, "")
+		CheckForbiddenStatus(t, resp)
+
+		converted, err := th.App.GetChannel(channel.Id)
+		require.Nil(t, err)
+		assert.False(t, converted.EnableFeed)
+		assert.Empty(t, converted.FeedKey)
+	})
+
+	t.Run("feeds disabled on the server", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableChannelFeeds = false })
+
+		_, resp := anonymous.GetChannelFeedAtom(channel.Id, feed.Token, "")
+		CheckNotImplementedStatus(t, resp)
+	})
+}
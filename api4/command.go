@@ -22,6 +22,7 @@ func (api *API) InitCommand() {
 
 	api.BaseRoutes.Team.Handle("/commands/autocomplete", api.ApiSessionRequired(listAutocompleteCommands)).Methods("GET")
 	api.BaseRoutes.Command.Handle("/regen_token", api.ApiSessionRequired(regenCommandToken)).Methods("PUT")
+	api.BaseRoutes.Command.Handle("/regen_signing_secret", api.ApiSessionRequired(regenCommandSigningSecret)).Methods("PUT")
 
 	api.BaseRoutes.Teams.Handle("/command_test", api.ApiHandler(testCommand)).Methods("POST")
 	api.BaseRoutes.Teams.Handle("/command_test", api.ApiHandler(testCommand)).Methods("GET")
@@ -225,6 +226,7 @@ func executeCommand(c *Context, w http.ResponseWriter, r *http.Request) {
 	commandArgs.T = c.T
 	commandArgs.Session = c.Session
 	commandArgs.SiteURL = c.GetSiteURLHeader()
+	commandArgs.RequestId = c.RequestId
 
 	response, err := c.App.ExecuteCommand(commandArgs)
 	if err != nil {
@@ -292,6 +294,44 @@ func regenCommandToken(c *Context, w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(model.MapToJson(resp)))
 }
 
+func regenCommandSigningSecret(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireCommandId()
+	if c.Err != nil {
+		return
+	}
+
+	c.LogAudit("attempt")
+	cmd, err := c.App.GetCommand(c.Params.CommandId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	if !c.App.SessionHasPermissionToTeam(c.Session, cmd.TeamId, model.PERMISSION_MANAGE_SLASH_COMMANDS) {
+		c.LogAudit("fail - inappropriate permissions")
+		c.SetPermissionError(model.PERMISSION_MANAGE_SLASH_COMMANDS)
+		return
+	}
+
+	if c.Session.UserId != cmd.CreatorId && !c.App.SessionHasPermissionToTeam(c.Session, cmd.TeamId, model.PERMISSION_MANAGE_OTHERS_SLASH_COMMANDS) {
+		c.LogAudit("fail - inappropriate permissions")
+		c.SetPermissionError(model.PERMISSION_MANAGE_OTHERS_SLASH_COMMANDS)
+		return
+	}
+
+	rcmd, err := c.App.RegenCommandSigningSecret(cmd)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("success")
+	resp := make(map[string]string)
+	resp["signing_secret"] = rcmd.SigningSecret
+
+	w.Write([]byte(model.MapToJson(resp)))
+}
+
 func testCommand(c *Context, w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
 
@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/mattermost/mattermost-server/model"
@@ -396,6 +397,99 @@ func TestRegenToken(t *testing.T) {
 	}
 }
 
+func TestRegenCommandSigningSecret(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	enableCommands := *th.App.Config().ServiceSettings.EnableCommands
+	defer func() {
+		th.App.UpdateConfig(func(cfg *model.Config) { cfg.ServiceSettings.EnableCommands = &enableCommands })
+	}()
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableCommands = true })
+
+	createdCmd, resp := th.SystemAdminClient.CreateCommand(&model.Command{
+		CreatorId: th.BasicUser.Id,
+		TeamId:    th.BasicTeam.Id,
+		URL:       "http://nowhere.com",
+		Method:    model.COMMAND_METHOD_POST,
+		Trigger:   "trigger"})
+	CheckNoError(t, resp)
+	require.NotEmpty(t, createdCmd.SigningSecret, "new commands should be signed")
+
+	secret, resp := th.SystemAdminClient.RegenCommandSigningSecret(createdCmd.Id)
+	CheckNoError(t, resp)
+	assert.NotEqual(t, createdCmd.SigningSecret, secret)
+	assert.Len(t, secret, model.INTEGRATION_SIGNING_SECRET_LENGTH)
+
+	secret, resp = Client.RegenCommandSigningSecret(createdCmd.Id)
+	CheckForbiddenStatus(t, resp)
+	assert.Equal(t, "", secret)
+}
+
+func TestExecuteSignedCommand(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	enableCommands := *th.App.Config().ServiceSettings.EnableCommands
+	allowedInternalConnections := *th.App.Config().ServiceSettings.AllowedUntrustedInternalConnections
+	defer func() {
+		th.App.UpdateConfig(func(cfg *model.Config) { cfg.ServiceSettings.EnableCommands = &enableCommands })
+		th.App.UpdateConfig(func(cfg *model.Config) {
+			cfg.ServiceSettings.AllowedUntrustedInternalConnections = &allowedInternalConnections
+		})
+	}()
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableCommands = true })
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.AllowedUntrustedInternalConnections = "127.0.0.0/8" })
+
+	var secret string
+	var query url.Values
+	var verifyErr *model.AppError
+	authorization := ""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Commands that use GET are signed with their query strings
+		verifyErr = model.VerifyIntegrationRequest(r.Header, []byte(r.URL.RawQuery), secret, model.GetMillis(), model.INTEGRATION_SIGNING_DEFAULT_TOLERANCE)
+		query = r.URL.Query()
+		authorization = r.Header.Get("Authorization")
+
+		w.Write([]byte((&model.CommandResponse{}).ToJson()))
+	}))
+	defer ts.Close()
+
+	cmd, err := th.App.CreateCommand(&model.Command{
+		CreatorId: th.BasicUser.Id,
+		TeamId:    th.BasicTeam.Id,
+		URL:       ts.URL,
+		Method:    model.COMMAND_METHOD_GET,
+		Trigger:   "signedcommand",
+	})
+	require.Nil(t, err)
+	secret = cmd.SigningSecret
+
+	_, resp := Client.ExecuteCommand(th.BasicChannel.Id, "/signedcommand hello")
+	CheckNoError(t, resp)
+	assert.Nil(t, verifyErr)
+	assert.Equal(t, "hello", query.Get("text"))
+	assert.Equal(t, cmd.Token, query.Get("token"), "the token should still be sent for compatibility")
+	assert.Equal(t, "Token "+cmd.Token, authorization)
+
+	cmd.DisableLegacyToken = true
+	cmd, err = th.App.UpdateCommand(cmd, cmd)
+	require.Nil(t, err)
+
+	_, resp = Client.ExecuteCommand(th.BasicChannel.Id, "/signedcommand hello")
+	CheckNoError(t, resp)
+	assert.Nil(t, verifyErr)
+	assert.NotContains(t, query, "token", "the token should be left out")
+	assert.Equal(t, "", authorization)
+
+	secret = model.NewRandomString(model.INTEGRATION_SIGNING_SECRET_LENGTH)
+	_, resp = Client.ExecuteCommand(th.BasicChannel.Id, "/signedcommand hello")
+	CheckNoError(t, resp)
+	assert.NotNil(t, verifyErr, "the request shouldn't be verified with another secret")
+}
+
 func TestExecuteInvalidCommand(t *testing.T) {
 	th := Setup().InitBasic().InitSystemAdmin()
 	defer th.TearDown()
@@ -459,6 +553,70 @@ func TestExecuteInvalidCommand(t *testing.T) {
 	CheckNoError(t, resp)
 }
 
+func TestExecuteCommandDisabledInChannel(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+	channel := th.BasicChannel
+
+	enableCommands := *th.App.Config().ServiceSettings.EnableCommands
+	allowedInternalConnections := *th.App.Config().ServiceSettings.AllowedUntrustedInternalConnections
+	defer func() {
+		th.App.UpdateConfig(func(cfg *model.Config) { cfg.ServiceSettings.EnableCommands = &enableCommands })
+		th.App.UpdateConfig(func(cfg *model.Config) {
+			cfg.ServiceSettings.AllowedUntrustedInternalConnections = &allowedInternalConnections
+		})
+	}()
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableCommands = true })
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.AllowedUntrustedInternalConnections = "127.0.0.0/8" })
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte((&model.CommandResponse{Text: "response"}).ToJson()))
+	}))
+	defer ts.Close()
+
+	getCmd := &model.Command{
+		CreatorId: th.BasicUser.Id,
+		TeamId:    th.BasicTeam.Id,
+		URL:       fmt.Sprintf("%s/%s/teams/command_test", ts.URL, model.API_URL_SUFFIX_V4),
+		Method:    model.COMMAND_METHOD_GET,
+		Trigger:   "getcommand",
+	}
+
+	if _, err := th.App.CreateCommand(getCmd); err != nil {
+		t.Fatal("failed to create get command")
+	}
+
+	_, resp := Client.PatchChannel(channel.Id, &model.ChannelPatch{DisabledCommandTriggers: &model.StringArray{"echo"}})
+	CheckNoError(t, resp)
+
+	_, resp = Client.ExecuteCommand(channel.Id, "/echo hello")
+	CheckForbiddenStatus(t, resp)
+	CheckErrorMessage(t, resp, "api.command.execute_command.disabled_in_channel.app_error")
+
+	_, resp = Client.ExecuteCommand(channel.Id, "/getcommand")
+	CheckNoError(t, resp)
+
+	_, resp = Client.ExecuteCommand(th.BasicChannel2.Id, "/echo hello")
+	CheckNoError(t, resp)
+
+	_, resp = Client.PatchChannel(channel.Id, &model.ChannelPatch{DisableCustomCommands: model.NewBool(true)})
+	CheckNoError(t, resp)
+
+	_, resp = Client.ExecuteCommand(channel.Id, "/getcommand")
+	CheckForbiddenStatus(t, resp)
+	CheckErrorMessage(t, resp, "api.command.execute_command.custom_disabled_in_channel.app_error")
+
+	_, resp = Client.ExecuteCommand(channel.Id, "/shrug")
+	CheckNoError(t, resp)
+
+	rchannel, resp := Client.GetChannel(channel.Id, "")
+	CheckNoError(t, resp)
+	require.True(t, rchannel.DisableCustomCommands)
+	require.Equal(t, model.StringArray{"echo"}, rchannel.DisabledCommandTriggers)
+}
+
 func TestExecuteGetCommand(t *testing.T) {
 	th := Setup().InitBasic().InitSystemAdmin()
 	defer th.TearDown()
@@ -484,6 +642,7 @@ func TestExecuteGetCommand(t *testing.T) {
 		Props:        map[string]interface{}{"someprop": "somevalue"},
 	}
 
+	requestId := ""
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		require.Equal(t, http.MethodGet, r.Method)
 
@@ -492,6 +651,7 @@ func TestExecuteGetCommand(t *testing.T) {
 
 		require.Equal(t, token, values.Get("token"))
 		require.Equal(t, th.BasicTeam.Name, values.Get("team_domain"))
+		requestId = r.Header.Get(model.HEADER_REQUEST_ID)
 
 		w.Header().Set("Content-Type", "application/json")
 		w.Write([]byte(expectedCommandResponse.ToJson()))
@@ -513,6 +673,7 @@ func TestExecuteGetCommand(t *testing.T) {
 
 	commandResponse, resp := Client.ExecuteCommand(channel.Id, "/getcommand")
 	CheckNoError(t, resp)
+	require.Equal(t, resp.RequestId, requestId, "should have passed along the request id")
 
 	expectedCommandResponse.Props["from_webhook"] = "true"
 	require.Equal(t, expectedCommandResponse, commandResponse)
@@ -0,0 +1,214 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func (api *API) InitCustomGroup() {
+	api.BaseRoutes.CustomGroups.Handle("", api.ApiSessionRequired(createCustomGroup)).Methods("POST")
+	api.BaseRoutes.CustomGroups.Handle("", api.ApiSessionRequired(getCustomGroups)).Methods("GET")
+	api.BaseRoutes.CustomGroup.Handle("", api.ApiSessionRequired(getCustomGroup)).Methods("GET")
+	api.BaseRoutes.CustomGroup.Handle("/patch", api.ApiSessionRequired(patchCustomGroup)).Methods("PUT")
+	api.BaseRoutes.CustomGroup.Handle("", api.ApiSessionRequired(deleteCustomGroup)).Methods("DELETE")
+	api.BaseRoutes.CustomGroup.Handle("/members", api.ApiSessionRequired(getCustomGroupMembers)).Methods("GET")
+	api.BaseRoutes.CustomGroup.Handle("/members", api.ApiSessionRequired(addCustomGroupMembers)).Methods("POST")
+	api.BaseRoutes.CustomGroup.Handle("/members/{user_id:[A-Za-z0-9]+}", api.ApiSessionRequired(removeCustomGroupMember)).Methods("DELETE")
+}
+
+// canManageCustomGroup makes sure that the session is allowed to change the group. Anyone with the
+// permission to manage custom groups can change the groups that they've created, but changing
+// someone else's group requires a system admin.
+func canManageCustomGroup(c *Context, group *model.CustomGroup) bool {
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_CUSTOM_GROUPS) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_CUSTOM_GROUPS)
+		return false
+	}
+
+	if group.CreatorId != c.Session.UserId && !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return false
+	}
+
+	return true
+}
+
+// getManageableCustomGroup returns the group from the URL if the session is allowed to change it.
+func getManageableCustomGroup(c *Context) *model.CustomGroup {
+	group, err := c.App.GetCustomGroup(c.Params.GroupId)
+	if err != nil {
+		c.Err = err
+		return nil
+	}
+
+	if !canManageCustomGroup(c, group) {
+		return nil
+	}
+
+	return group
+}
+
+func createCustomGroup(c *Context, w http.ResponseWriter, r *http.Request) {
+	group := model.CustomGroupFromJson(r.Body)
+	if group == nil {
+		c.SetInvalidParam("group")
+		return
+	}
+
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_CUSTOM_GROUPS) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_CUSTOM_GROUPS)
+		return
+	}
+
+	created, err := c.App.CreateCustomGroup(group, c.Session.UserId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("name=" + created.Name)
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(created.ToJson()))
+}
+
+func getCustomGroups(c *Context, w http.ResponseWriter, r *http.Request) {
+	groups, err := c.App.GetCustomGroups(c.Params.Page, c.Params.PerPage)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(model.CustomGroupListToJson(groups)))
+}
+
+func getCustomGroup(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireGroupId()
+	if c.Err != nil {
+		return
+	}
+
+	group, err := c.App.GetCustomGroup(c.Params.GroupId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(group.ToJson()))
+}
+
+func patchCustomGroup(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireGroupId()
+	if c.Err != nil {
+		return
+	}
+
+	patch := model.CustomGroupPatchFromJson(r.Body)
+	if patch == nil {
+		c.SetInvalidParam("group")
+		return
+	}
+
+	group := getManageableCustomGroup(c)
+	if group == nil {
+		return
+	}
+
+	patched, err := c.App.PatchCustomGroup(group, patch)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("name=" + patched.Name)
+	w.Write([]byte(patched.ToJson()))
+}
+
+func deleteCustomGroup(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireGroupId()
+	if c.Err != nil {
+		return
+	}
+
+	group := getManageableCustomGroup(c)
+	if group == nil {
+		return
+	}
+
+	if err := c.App.DeleteCustomGroup(group.Id); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("name=" + group.Name)
+	ReturnStatusOK(w)
+}
+
+func getCustomGroupMembers(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireGroupId()
+	if c.Err != nil {
+		return
+	}
+
+	if _, err := c.App.GetCustomGroup(c.Params.GroupId); err != nil {
+		c.Err = err
+		return
+	}
+
+	members, err := c.App.GetCustomGroupMembers(c.Params.GroupId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(model.GroupMemberListToJson(members)))
+}
+
+func addCustomGroupMembers(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireGroupId()
+	if c.Err != nil {
+		return
+	}
+
+	userIds := model.ArrayFromJson(r.Body)
+	if len(userIds) == 0 {
+		c.SetInvalidParam("user_ids")
+		return
+	}
+
+	group := getManageableCustomGroup(c)
+	if group == nil {
+		return
+	}
+
+	members, err := c.App.AddCustomGroupMembers(group.Id, userIds)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(model.GroupMemberListToJson(members)))
+}
+
+func removeCustomGroupMember(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireGroupId().RequireUserId()
+	if c.Err != nil {
+		return
+	}
+
+	group := getManageableCustomGroup(c)
+	if group == nil {
+		return
+	}
+
+	if err := c.App.RemoveCustomGroupMember(group.Id, c.Params.UserId); err != nil {
+		c.Err = err
+		return
+	}
+
+	ReturnStatusOK(w)
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestCreateCustomGroup(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	group, resp := Client.CreateCustomGroup(&model.CustomGroup{Name: "G" + model.NewId(), DisplayName: "Group", CreatorId: th.BasicUser2.Id})
+	CheckNoError(t, resp)
+	CheckCreatedStatus(t, resp)
+	assert.Equal(t, th.BasicUser.Id, group.CreatorId)
+
+	_, resp = Client.CreateCustomGroup(&model.CustomGroup{Name: group.Name, DisplayName: "Group"})
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = Client.CreateCustomGroup(&model.CustomGroup{Name: "all", DisplayName: "Group"})
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = Client.CreateCustomGroup(&model.CustomGroup{Name: th.BasicUser2.Username, DisplayName: "Group"})
+	CheckBadRequestStatus(t, resp)
+
+	fetched, resp := Client.GetCustomGroup(group.Id)
+	CheckNoError(t, resp)
+	assert.Equal(t, group.Name, fetched.Name)
+
+	_, resp = Client.GetCustomGroup(model.NewId())
+	CheckNotFoundStatus(t, resp)
+
+	groups, resp := Client.GetCustomGroups(0, 1000)
+	CheckNoError(t, resp)
+	found := false
+	for _, g := range groups {
+		found = found || g.Id == group.Id
+	}
+	assert.True(t, found)
+
+	th.RemovePermissionFromRole(model.PERMISSION_MANAGE_CUSTOM_GROUPS.Id, model.SYSTEM_USER_ROLE_ID)
+	defer th.AddPermissionToRole(model.PERMISSION_MANAGE_CUSTOM_GROUPS.Id, model.SYSTEM_USER_ROLE_ID)
+
+	_, resp = Client.CreateCustomGroup(&model.CustomGroup{Name: "g" + model.NewId(), DisplayName: "Group"})
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = th.SystemAdminClient.CreateCustomGroup(&model.CustomGroup{Name: "g" + model.NewId(), DisplayName: "Group"})
+	CheckNoError(t, resp)
+
+	Client.Logout()
+	_, resp = Client.GetCustomGroups(0, 60)
+	CheckUnauthorizedStatus(t, resp)
+}
+
+func TestPatchAndDeleteCustomGroup(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	group, resp := Client.CreateCustomGroup(&model.CustomGroup{Name: "g" + model.NewId(), DisplayName: "Group"})
+	CheckNoError(t, resp)
+
+	displayName := "Renamed"
+	patched, resp := Client.PatchCustomGroup(group.Id, &model.CustomGroupPatch{DisplayName: &displayName})
+	CheckNoError(t, resp)
+	assert.Equal(t, "Renamed", patched.DisplayName)
+	assert.Equal(t, group.Name, patched.Name)
+
+	invalidName := "not valid"
+	_, resp = Client.PatchCustomGroup(group.Id, &model.CustomGroupPatch{Name: &invalidName})
+	CheckBadRequestStatus(t, resp)
+
+	// Only the creator or a system admin can change a group
+	th.LoginBasic2()
+	_, resp = Client.PatchCustomGroup(group.Id, &model.CustomGroupPatch{DisplayName: &displayName})
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = Client.DeleteCustomGroup(group.Id)
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = th.SystemAdminClient.PatchCustomGroup(group.Id, &model.CustomGroupPatch{DisplayName: &displayName})
+	CheckNoError(t, resp)
+
+	th.LoginBasic()
+	ok, resp := Client.DeleteCustomGroup(group.Id)
+	CheckNoError(t, resp)
+	assert.True(t, ok)
+
+	_, resp = Client.GetCustomGroup(group.Id)
+	CheckNotFoundStatus(t, resp)
+
+	_, resp = Client.DeleteCustomGroup(group.Id)
+	CheckNotFoundStatus(t, resp)
+}
+
+func TestCustomGroupMembers(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+	Client := th.Client
+
+	group, resp := Client.CreateCustomGroup(&model.CustomGroup{Name: "g" + model.NewId(), DisplayName: "Group"})
+	CheckNoError(t, resp)
+
+	members, resp := Client.AddCustomGroupMembers(group.Id, []string{th.BasicUser.Id, th.BasicUser2.Id})
+	CheckNoError(t, resp)
+	CheckCreatedStatus(t, resp)
+	assert.Len(t, members, 2)
+
+	_, resp = Client.AddCustomGroupMembers(group.Id, []string{model.NewId()})
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = Client.AddCustomGroupMembers(group.Id, []string{})
+	CheckBadRequestStatus(t, resp)
+
+	members, resp = Client.GetCustomGroupMembers(group.Id)
+	CheckNoError(t, resp)
+	require.Len(t, members, 2)
+
+	ok, resp := Client.RemoveCustomGroupMember(group.Id, th.BasicUser2.Id)
+	CheckNoError(t, resp)
+	assert.True(t, ok)
+
+	members, resp = Client.GetCustomGroupMembers(group.Id)
+	CheckNoError(t, resp)
+	require.Len(t, members, 1)
+	assert.Equal(t, th.BasicUser.Id, members[0].UserId)
+
+	th.LoginBasic2()
+	_, resp = Client.AddCustomGroupMembers(group.Id, []string{th.BasicUser2.Id})
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = Client.RemoveCustomGroupMember(group.Id, th.BasicUser.Id)
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = Client.GetCustomGroupMembers(model.NewId())
+	CheckNotFoundStatus(t, resp)
+}
+
+func TestAutocompleteCustomGroups(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+	Client := th.Client
+
+	name := "g" + model.NewId()
+	group, resp := Client.CreateCustomGroup(&model.CustomGroup{Name: name, DisplayName: "Group"})
+	CheckNoError(t, resp)
+
+	rusers, resp := Client.AutocompleteUsersInChannel(th.BasicTeam.Id, th.BasicChannel.Id, name[:10], "")
+	CheckNoError(t, resp)
+	require.Len(t, rusers.Groups, 1)
+	assert.Equal(t, group.Id, rusers.Groups[0].Id)
+
+	rusers, resp = Client.AutocompleteUsersInTeam(th.BasicTeam.Id, name[:10], "")
+	CheckNoError(t, resp)
+	require.Len(t, rusers.Groups, 1)
+
+	rusers, resp = Client.AutocompleteUsersInChannel(th.BasicTeam.Id, th.BasicChannel.Id, "", "")
+	CheckNoError(t, resp)
+	assert.Len(t, rusers.Groups, 0)
+
+	disabled := true
+	_, resp = Client.PatchChannel(th.BasicChannel.Id, &model.ChannelPatch{DisableGroupMentions: &disabled})
+	CheckNoError(t, resp)
+
+	rusers, resp = Client.AutocompleteUsersInChannel(th.BasicTeam.Id, th.BasicChannel.Id, name[:10], "")
+	CheckNoError(t, resp)
+	assert.Len(t, rusers.Groups, 0)
+}
@@ -5,6 +5,7 @@ package api4
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/mattermost/mattermost-server/app"
@@ -21,6 +22,8 @@ func (api *API) InitEmoji() {
 	api.BaseRoutes.Emojis.Handle("", api.ApiSessionRequired(getEmojiList)).Methods("GET")
 	api.BaseRoutes.Emojis.Handle("/search", api.ApiSessionRequired(searchEmojis)).Methods("POST")
 	api.BaseRoutes.Emojis.Handle("/autocomplete", api.ApiSessionRequired(autocompleteEmojis)).Methods("GET")
+	api.BaseRoutes.Emojis.Handle("/stats", api.ApiPermissionRequired(model.PERMISSION_READ_SYSTEM_CONSOLE, getEmojiStats)).Methods("GET")
+	api.BaseRoutes.Emojis.Handle("/unused", api.ApiSessionRequired(deleteUnusedEmoji)).Methods("DELETE")
 	api.BaseRoutes.Emoji.Handle("", api.ApiSessionRequired(deleteEmoji)).Methods("DELETE")
 	api.BaseRoutes.Emoji.Handle("", api.ApiSessionRequired(getEmoji)).Methods("GET")
 	api.BaseRoutes.EmojiByName.Handle("", api.ApiSessionRequired(getEmojiByName)).Methods("GET")
@@ -98,6 +101,41 @@ func getEmojiList(c *Context, w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func getEmojiStats(c *Context, w http.ResponseWriter, r *http.Request) {
+	stats, err := c.App.GetEmojiStats(c.Params.Page, c.Params.PerPage)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(model.EmojiStatsListToJson(stats)))
+}
+
+func deleteUnusedEmoji(c *Context, w http.ResponseWriter, r *http.Request) {
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	days, convErr := strconv.Atoi(r.URL.Query().Get("days"))
+	if convErr != nil || days < 1 {
+		c.SetInvalidUrlParam("days")
+		return
+	}
+
+	deleted, err := c.App.DeleteUnusedEmoji(days)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	for _, emoji := range deleted {
+		c.LogAudit("deleted unused emoji name=" + emoji.Name)
+	}
+
+	w.Write([]byte(model.EmojiListToJson(deleted)))
+}
+
 func deleteEmoji(c *Context, w http.ResponseWriter, r *http.Request) {
 	c.RequireEmojiId()
 	if c.Err != nil {
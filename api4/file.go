@@ -5,6 +5,7 @@ package api4
 
 import (
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -31,26 +32,26 @@ var UNSAFE_CONTENT_TYPES = [...]string{
 	"text/html",
 }
 
-var MEDIA_CONTENT_TYPES = [...]string{
-	"image/jpeg",
-	"image/png",
-	"image/bmp",
-	"image/gif",
-	"video/avi",
-	"video/mpeg",
-	"video/mp4",
-	"audio/mpeg",
-	"audio/wav",
-}
-
 func (api *API) InitFile() {
 	api.BaseRoutes.Files.Handle("", api.ApiSessionRequired(uploadFile)).Methods("POST")
 	api.BaseRoutes.File.Handle("", api.ApiSessionRequiredTrustRequester(getFile)).Methods("GET")
 	api.BaseRoutes.File.Handle("/thumbnail", api.ApiSessionRequiredTrustRequester(getFileThumbnail)).Methods("GET")
 	api.BaseRoutes.File.Handle("/link", api.ApiSessionRequired(getFileLink)).Methods("GET")
+	api.BaseRoutes.File.Handle("/links", api.ApiSessionRequired(createFilePublicLink)).Methods("POST")
 	api.BaseRoutes.File.Handle("/preview", api.ApiSessionRequiredTrustRequester(getFilePreview)).Methods("GET")
 	api.BaseRoutes.File.Handle("/info", api.ApiSessionRequired(getFileInfo)).Methods("GET")
 
+	api.BaseRoutes.User.Handle("/files", api.ApiSessionRequired(getUserFiles)).Methods("GET")
+	api.BaseRoutes.User.Handle("/files/{file_id:[A-Za-z0-9]+}", api.ApiSessionRequired(deleteUserFile)).Methods("DELETE")
+
+	api.BaseRoutes.User.Handle("/storage_exempt", api.ApiSessionRequired(updateUserStorageExempt)).Methods("PUT")
+
+	api.BaseRoutes.FileUsage.Handle("", api.ApiPermissionRequired(model.PERMISSION_READ_SYSTEM_CONSOLE, getTopFileStorageUsage)).Methods("GET")
+
+	api.BaseRoutes.FileLinks.Handle("", api.ApiSessionRequired(getFilePublicLinks)).Methods("GET")
+	api.BaseRoutes.FileLinks.Handle("/{link_id:[A-Za-z0-9]+}", api.ApiSessionRequired(revokeFilePublicLink)).Methods("DELETE")
+	api.BaseRoutes.Channel.Handle("/files/public_links", api.ApiSessionRequired(getChannelFilePublicLinks)).Methods("GET")
+
 	api.BaseRoutes.PublicFile.Handle("", api.ApiHandler(getPublicFile)).Methods("GET")
 
 }
@@ -152,13 +153,16 @@ func getFile(c *Context, w http.ResponseWriter, r *http.Request) {
 	}
 
 	data, err := c.App.ReadFile(info.Path)
-	if err != nil {
+	if err != nil && info.MissingAt != 0 {
+		c.Err = model.NewAppError("getFile", "api.file.get_file.missing.app_error", nil, "file_id="+info.Id+", "+err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
 		c.Err = err
 		c.Err.StatusCode = http.StatusNotFound
 		return
 	}
 
-	err = writeFileResponse(info.Name, info.MimeType, data, forceDownload, w, r)
+	err = writeFileResponse(info.Name, c.App.GetFileContentType(info, data), c.App.Config().FileSettings.InlineContentTypes, data, forceDownload, w, r)
 	if err != nil {
 		c.Err = err
 		return
@@ -195,7 +199,7 @@ func getFileThumbnail(c *Context, w http.ResponseWriter, r *http.Request) {
 	if data, err := c.App.ReadFile(info.ThumbnailPath); err != nil {
 		c.Err = err
 		c.Err.StatusCode = http.StatusNotFound
-	} else if err := writeFileResponse(info.Name, THUMBNAIL_IMAGE_TYPE, data, forceDownload, w, r); err != nil {
+	} else if err := writeFileResponse(info.Name, THUMBNAIL_IMAGE_TYPE, c.App.Config().FileSettings.InlineContentTypes, data, forceDownload, w, r); err != nil {
 		c.Err = err
 		return
 	}
@@ -228,12 +232,260 @@ func getFileLink(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	link, err := c.App.GetOrCreateFilePublicLink(info.Id, c.Session.UserId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
 	resp := make(map[string]string)
-	resp["link"] = c.App.GeneratePublicLink(c.GetSiteURLHeader(), info)
+	resp["link"] = link.Url(c.GetSiteURLHeader())
 
 	w.Write([]byte(model.MapToJson(resp)))
 }
 
+func createFilePublicLink(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireFileId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.Config().FileSettings.EnablePublicLink {
+		c.Err = model.NewAppError("createFilePublicLink", "api.file.get_public_link.disabled.app_error", nil, "", http.StatusNotImplemented)
+		return
+	}
+
+	link := model.FilePublicLinkFromJson(r.Body)
+	if link == nil {
+		c.SetInvalidParam("file_public_link")
+		return
+	}
+
+	info, err := c.App.GetFileInfo(c.Params.FileId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	if info.CreatorId != c.Session.UserId && !c.App.SessionHasPermissionToChannelByPost(c.Session, info.PostId, model.PERMISSION_READ_CHANNEL) {
+		c.SetPermissionError(model.PERMISSION_READ_CHANNEL)
+		return
+	}
+
+	link.FileId = info.Id
+	link.CreatorId = c.Session.UserId
+
+	rlink, err := c.App.CreateFilePublicLink(link)
+	if err != nil {
+		c.Err = err
+		return
+	}
+	rlink.Link = rlink.Url(c.GetSiteURLHeader())
+
+	c.LogAudit("file_id=" + rlink.FileId + " link_id=" + rlink.Id)
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(rlink.ToJson()))
+}
+
+// getTopFileStorageUsage lists the users or teams, depending on the type parameter, that are using the most storage.
+func getTopFileStorageUsage(c *Context, w http.ResponseWriter, r *http.Request) {
+	usageType := r.URL.Query().Get("type")
+	if !model.IsValidFileStorageUsageType(usageType) {
+		c.SetInvalidUrlParam("type")
+		return
+	}
+
+	usages, err := c.App.GetTopFileStorageUsage(usageType, c.Params.Page, c.Params.PerPage)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(model.FileStorageUsageListToJson(usages)))
+}
+
+func updateUserStorageExempt(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	props := model.StringInterfaceFromJson(r.Body)
+
+	exempt, ok := props["exempt"].(bool)
+	if !ok {
+		c.SetInvalidParam("exempt")
+		return
+	}
+
+	if err := c.App.SetUserFileStorageExempt(c.Params.UserId, exempt); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAuditWithUserId(c.Params.UserId, "storage_exempt="+strconv.FormatBool(exempt))
+	ReturnStatusOK(w)
+}
+
+func getFilePublicLinks(c *Context, w http.ResponseWriter, r *http.Request) {
+	links, err := c.App.GetActiveFilePublicLinksForUser(c.Session.UserId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	for _, link := range links {
+		link.Link = link.Url(c.GetSiteURLHeader())
+	}
+
+	w.Write([]byte(model.FilePublicLinkListToJson(links)))
+}
+
+// getChannelFilePublicLinks returns the links to files posted in a channel so that anyone who can manage the channel
+// can find the links to revoke.
+func getChannelFilePublicLinks(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireChannelId()
+	if c.Err != nil {
+		return
+	}
+
+	channel, err := c.App.GetChannel(c.Params.ChannelId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	if !canRevokeChannelFilePublicLinks(c, channel) {
+		return
+	}
+
+	links, err := c.App.GetActiveFilePublicLinksForChannel(channel.Id)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(model.FilePublicLinkListToJson(links)))
+}
+
+// revokeFilePublicLink lets the creator of a link revoke it, as well as anyone who can manage the channel that the
+// file was posted in.
+func revokeFilePublicLink(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireLinkId()
+	if c.Err != nil {
+		return
+	}
+
+	link, err := c.App.GetFilePublicLink(c.Params.LinkId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	if link.CreatorId != c.Session.UserId {
+		channel, err := c.App.GetChannel(link.ChannelId)
+		if err != nil {
+			c.Err = err
+			return
+		}
+
+		if !canRevokeChannelFilePublicLinks(c, channel) {
+			return
+		}
+	}
+
+	if _, err := c.App.RevokeFilePublicLink(link.Id); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("file_id=" + link.FileId + " link_id=" + link.Id)
+	ReturnStatusOK(w)
+}
+
+// canRevokeChannelFilePublicLinks returns whether the session can see and revoke other users' links to files in the
+// channel, setting the permission error if it can't.
+func canRevokeChannelFilePublicLinks(c *Context, channel *model.Channel) bool {
+	// Nobody manages direct and group messages, so only system admins can revoke other users' links in them
+	if channel.IsGroupOrDirect() {
+		if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+			c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+			return false
+		}
+		return true
+	}
+
+	return CanManageChannel(c, channel)
+}
+
+// getUserFiles returns the files uploaded by a user, matching the query's team_id, channel_id, created_after and
+// created_before parameters.
+func getUserFiles(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionToUser(c.Session, c.Params.UserId) {
+		c.SetPermissionError(model.PERMISSION_EDIT_OTHER_USERS)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := &model.FileInfoFilter{
+		CreatorId: c.Params.UserId,
+		TeamId:    query.Get("team_id"),
+		ChannelId: query.Get("channel_id"),
+	}
+
+	for param, value := range map[string]*int64{
+		"created_after":  &filter.CreatedAfter,
+		"created_before": &filter.CreatedBefore,
+	} {
+		if str := query.Get(param); str != "" {
+			millis, err := strconv.ParseInt(str, 10, 64)
+			if err != nil || millis < 0 {
+				c.SetInvalidUrlParam(param)
+				return
+			}
+			*value = millis
+		}
+	}
+
+	files, err := c.App.GetUserFiles(filter, c.Params.Page, c.Params.PerPage)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(files.ToJson()))
+}
+
+func deleteUserFile(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId().RequireFileId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionToUser(c.Session, c.Params.UserId) {
+		c.SetPermissionError(model.PERMISSION_EDIT_OTHER_USERS)
+		return
+	}
+
+	if err := c.App.DeleteUserFile(c.Params.UserId, c.Params.FileId); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("user_id=" + c.Params.UserId + " file_id=" + c.Params.FileId)
+	ReturnStatusOK(w)
+}
+
 func getFilePreview(c *Context, w http.ResponseWriter, r *http.Request) {
 	c.RequireFileId()
 	if c.Err != nil {
@@ -256,6 +508,18 @@ func getFilePreview(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if info.HasPreviewText {
+		preview, err := c.App.GetFileTextPreview(info)
+		if err != nil {
+			c.Err = err
+			return
+		}
+
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Write([]byte(preview.ToJson()))
+		return
+	}
+
 	if info.PreviewPath == "" {
 		c.Err = model.NewAppError("getFilePreview", "api.file.get_file_preview.no_preview.app_error", nil, "file_id="+info.Id, http.StatusBadRequest)
 		return
@@ -264,7 +528,7 @@ func getFilePreview(c *Context, w http.ResponseWriter, r *http.Request) {
 	if data, err := c.App.ReadFile(info.PreviewPath); err != nil {
 		c.Err = err
 		c.Err.StatusCode = http.StatusNotFound
-	} else if err := writeFileResponse(info.Name, PREVIEW_IMAGE_TYPE, data, forceDownload, w, r); err != nil {
+	} else if err := writeFileResponse(info.Name, PREVIEW_IMAGE_TYPE, c.App.Config().FileSettings.InlineContentTypes, data, forceDownload, w, r); err != nil {
 		c.Err = err
 		return
 	}
@@ -308,63 +572,62 @@ func getPublicFile(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hash := r.URL.Query().Get("h")
+	if linkId := r.URL.Query().Get("t"); len(linkId) != 0 {
+		if err := c.App.CountFilePublicLinkDownload(linkId, info.Id); err != nil {
+			c.Err = err
+			utils.RenderWebAppError(w, r, c.Err, c.App.AsymmetricSigningKey())
+			return
+		}
+	} else {
+		// Links made from the public link salt before links were saved can't be revoked, so they only work if
+		// they're allowed
+		hash := r.URL.Query().Get("h")
 
-	if len(hash) == 0 {
-		c.Err = model.NewAppError("getPublicFile", "api.file.get_file.public_invalid.app_error", nil, "", http.StatusBadRequest)
-		utils.RenderWebAppError(w, r, c.Err, c.App.AsymmetricSigningKey())
-		return
-	}
+		if len(hash) == 0 || !*c.App.Config().FileSettings.EnableLegacyPublicLinks {
+			c.Err = model.NewAppError("getPublicFile", "api.file.get_file.public_invalid.app_error", nil, "", http.StatusBadRequest)
+			utils.RenderWebAppError(w, r, c.Err, c.App.AsymmetricSigningKey())
+			return
+		}
 
-	if hash != app.GeneratePublicLinkHash(info.Id, *c.App.Config().FileSettings.PublicLinkSalt) {
-		c.Err = model.NewAppError("getPublicFile", "api.file.get_file.public_invalid.app_error", nil, "", http.StatusBadRequest)
-		utils.RenderWebAppError(w, r, c.Err, c.App.AsymmetricSigningKey())
-		return
+		if hash != app.GeneratePublicLinkHash(info.Id, *c.App.Config().FileSettings.PublicLinkSalt) {
+			c.Err = model.NewAppError("getPublicFile", "api.file.get_file.public_invalid.app_error", nil, "", http.StatusBadRequest)
+			utils.RenderWebAppError(w, r, c.Err, c.App.AsymmetricSigningKey())
+			return
+		}
 	}
 
 	if data, err := c.App.ReadFile(info.Path); err != nil {
 		c.Err = err
 		c.Err.StatusCode = http.StatusNotFound
-	} else if err := writeFileResponse(info.Name, info.MimeType, data, true, w, r); err != nil {
+	} else if err := writeFileResponse(info.Name, c.App.GetFileContentType(info, data), c.App.Config().FileSettings.InlineContentTypes, data, true, w, r); err != nil {
 		c.Err = err
 		return
 	}
 }
 
-func writeFileResponse(filename string, contentType string, bytes []byte, forceDownload bool, w http.ResponseWriter, r *http.Request) *model.AppError {
+// writeFileResponse writes the file to the response with the given content type. Only files with one of the inline
+// content types are shown in the browser, and the rest are downloaded.
+func writeFileResponse(filename string, contentType string, inlineContentTypes []string, bytes []byte, forceDownload bool, w http.ResponseWriter, r *http.Request) *model.AppError {
 	w.Header().Set("Cache-Control", "max-age=2592000, private")
 	w.Header().Set("Content-Length", strconv.Itoa(len(bytes)))
 	w.Header().Set("X-Content-Type-Options", "nosniff")
 
 	if contentType == "" {
 		contentType = "application/octet-stream"
-	} else {
-		for _, unsafeContentType := range UNSAFE_CONTENT_TYPES {
-			if strings.HasPrefix(contentType, unsafeContentType) {
-				contentType = "text/plain"
-				break
-			}
-		}
 	}
 
-	w.Header().Set("Content-Type", contentType)
-
-	var toDownload bool
-	if forceDownload {
-		toDownload = true
-	} else {
-		isMediaType := false
+	// This is decided before unsafe types are replaced, so that they're always downloaded
+	toDownload := forceDownload || !isInlineContentType(contentType, inlineContentTypes)
 
-		for _, mediaContentType := range MEDIA_CONTENT_TYPES {
-			if strings.HasPrefix(contentType, mediaContentType) {
-				isMediaType = true
-				break
-			}
+	for _, unsafeContentType := range UNSAFE_CONTENT_TYPES {
+		if strings.HasPrefix(contentType, unsafeContentType) {
+			contentType = "text/plain"
+			break
 		}
-
-		toDownload = !isMediaType
 	}
 
+	w.Header().Set("Content-Type", contentType)
+
 	filename = url.PathEscape(filename)
 
 	if toDownload {
@@ -381,3 +644,18 @@ func writeFileResponse(filename string, contentType string, bytes []byte, forceD
 
 	return nil
 }
+
+func isInlineContentType(contentType string, inlineContentTypes []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, inlineContentType := range inlineContentTypes {
+		if strings.EqualFold(mediaType, inlineContentType) {
+			return true
+		}
+	}
+
+	return false
+}
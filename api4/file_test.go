@@ -10,6 +10,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/store"
 )
@@ -324,6 +327,10 @@ func TestGetFileHeaders(t *testing.T) {
 				t.Fatal("returned incorrect Content-Type", contentType)
 			}
 
+			if contentTypeOptions := resp.Header.Get("X-Content-Type-Options"); contentTypeOptions != "nosniff" {
+				t.Fatal("returned incorrect X-Content-Type-Options", contentTypeOptions)
+			}
+
 			if getInline {
 				if contentDisposition := resp.Header.Get("Content-Disposition"); !strings.HasPrefix(contentDisposition, "inline") {
 					t.Fatal("returned incorrect Content-Disposition", contentDisposition)
@@ -353,8 +360,8 @@ func TestGetFileHeaders(t *testing.T) {
 	t.Run("gif", testHeaders(data, "test.gif", "image/gif", true))
 	t.Run("mp4", testHeaders(data, "test.mp4", "video/mp4", true))
 	t.Run("mp3", testHeaders(data, "test.mp3", "audio/mpeg", true))
-	t.Run("pdf", testHeaders(data, "test.pdf", "application/pdf", false))
-	t.Run("txt", testHeaders(data, "test.txt", "text/plain", false))
+	t.Run("pdf", testHeaders(data, "test.pdf", "application/pdf", true))
+	t.Run("txt", testHeaders(data, "test.txt", "text/plain", true))
 	t.Run("html", testHeaders(data, "test.html", "text/plain", false))
 	t.Run("js", testHeaders(data, "test.js", "text/plain", false))
 	t.Run("go", testHeaders(data, "test.go", "application/octet-stream", false))
@@ -362,7 +369,22 @@ func TestGetFileHeaders(t *testing.T) {
 	// Not every platform can recognize these
 	//t.Run("exe", testHeaders(data, "test.exe", "application/x-ms", false))
 	t.Run("no extension", testHeaders(data, "test", "application/octet-stream", false))
-	t.Run("no extension 2", testHeaders([]byte("<html></html>"), "test", "application/octet-stream", false))
+	t.Run("no extension 2", testHeaders([]byte("<html></html>"), "test", "text/plain", false))
+
+	pngData, err := readTestFile("test.png")
+	require.Nil(t, err)
+
+	// The type is detected from the contents, so renaming a file doesn't change how it's shown
+	t.Run("png without extension", testHeaders(pngData, "image", "image/png", true))
+	t.Run("html named as png", testHeaders([]byte("<html><body><script>alert('hello')</script></body></html>"), "image.png", "text/plain", false))
+
+	t.Run("not inline content type", func(t *testing.T) {
+		inlineContentTypes := th.App.Config().FileSettings.InlineContentTypes
+		defer th.App.UpdateConfig(func(cfg *model.Config) { cfg.FileSettings.InlineContentTypes = inlineContentTypes })
+		th.App.UpdateConfig(func(cfg *model.Config) { cfg.FileSettings.InlineContentTypes = []string{"image/gif"} })
+
+		testHeaders(pngData, "test.png", "image/png", false)(t)
+	})
 }
 
 func TestGetFileThumbnail(t *testing.T) {
@@ -544,6 +566,45 @@ func TestGetFilePreview(t *testing.T) {
 	CheckNoError(t, resp)
 }
 
+func TestGetFileTextPreview(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+	channel := th.BasicChannel
+
+	if *th.App.Config().FileSettings.DriverName == "" {
+		t.Skip("skipping because no file driver is enabled")
+	}
+
+	fileResp, resp := Client.UploadFile([]byte("<b>package main</b>\n"), channel.Id, "main.go")
+	CheckNoError(t, resp)
+	info := fileResp.FileInfos[0]
+	assert.True(t, info.HasPreviewText)
+	assert.Equal(t, "go", info.Language)
+
+	preview, resp := Client.GetFileTextPreview(info.Id)
+	CheckNoError(t, resp)
+	assert.Equal(t, "&lt;b&gt;package main&lt;/b&gt;\n", preview.Html)
+	assert.Equal(t, "go", preview.Language)
+	assert.Equal(t, model.FILE_TEXT_ENCODING_UTF8, preview.Encoding)
+	assert.False(t, preview.Truncated)
+
+	pngFile, err := readTestFile("test.png")
+	require.Nil(t, err)
+	fileResp, resp = Client.UploadFile(pngFile, channel.Id, "test.txt")
+	CheckNoError(t, resp)
+	assert.False(t, fileResp.FileInfos[0].HasPreviewText, "binary files shouldn't be previewed as text")
+
+	_, resp = Client.GetFileTextPreview(fileResp.FileInfos[0].Id)
+	CheckBadRequestStatus(t, resp)
+
+	otherUser := th.CreateUser()
+	Client.Logout()
+	Client.Login(otherUser.Email, otherUser.Password)
+	_, resp = Client.GetFileTextPreview(info.Id)
+	CheckForbiddenStatus(t, resp)
+}
+
 func TestGetFileInfo(t *testing.T) {
 	th := Setup().InitBasic().InitSystemAdmin()
 	defer th.TearDown()
@@ -682,3 +743,361 @@ func TestGetPublicFile(t *testing.T) {
 
 	th.cleanupTestFile(info)
 }
+
+func TestFilePublicLinks(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	if *th.App.Config().FileSettings.DriverName == "" {
+		t.Skip("skipping because no file driver is enabled")
+	}
+
+	enablePublicLink := th.App.Config().FileSettings.EnablePublicLink
+	enableLegacyPublicLinks := *th.App.Config().FileSettings.EnableLegacyPublicLinks
+	defer th.App.UpdateConfig(func(cfg *model.Config) {
+		cfg.FileSettings.EnablePublicLink = enablePublicLink
+		*cfg.FileSettings.EnableLegacyPublicLinks = enableLegacyPublicLinks
+	})
+	th.App.UpdateConfig(func(cfg *model.Config) { cfg.FileSettings.EnablePublicLink = true })
+
+	data, err := readTestFile("test.png")
+	require.NoError(t, err)
+
+	fileResp, resp := Client.UploadFile(data, th.BasicChannel.Id, "test.png")
+	CheckNoError(t, resp)
+	info := fileResp.FileInfos[0]
+	defer th.cleanupTestFile(info)
+
+	_, resp = Client.CreateFilePublicLink(info.Id, &model.FilePublicLink{})
+	CheckBadRequestStatus(t, resp)
+
+	// Hacky way to assign file to a post (usually would be done by CreatePost call)
+	store.Must(th.App.Srv.Store.FileInfo().AttachToPost(info.Id, th.BasicPost.Id))
+
+	// Wait a bit for files to ready
+	time.Sleep(2 * time.Second)
+
+	download := func(link string) int {
+		r, err := http.Get(link)
+		require.Nil(t, err)
+		defer r.Body.Close()
+		return r.StatusCode
+	}
+
+	t.Run("download limit", func(t *testing.T) {
+		link, resp := Client.CreateFilePublicLink(info.Id, &model.FilePublicLink{MaxDownloads: 2})
+		CheckNoError(t, resp)
+		CheckCreatedStatus(t, resp)
+		assert.Equal(t, th.BasicChannel.Id, link.ChannelId)
+		assert.NotEmpty(t, link.Link)
+
+		url := link.Url(Client.Url)
+		assert.Equal(t, http.StatusOK, download(url))
+		assert.Equal(t, http.StatusOK, download(url))
+		assert.Equal(t, http.StatusGone, download(url), "the link should be used up")
+
+		links, resp := Client.GetFilePublicLinks()
+		CheckNoError(t, resp)
+		for _, l := range links {
+			assert.NotEqual(t, link.Id, l.Id, "used up links shouldn't be listed")
+		}
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		_, resp := Client.CreateFilePublicLink(info.Id, &model.FilePublicLink{ExpiresAt: model.GetMillis() - 1000})
+		CheckBadRequestStatus(t, resp)
+
+		link, resp := Client.CreateFilePublicLink(info.Id, &model.FilePublicLink{ExpiresAt: model.GetMillis() + 1000})
+		CheckNoError(t, resp)
+
+		url := link.Url(Client.Url)
+		assert.Equal(t, http.StatusOK, download(url))
+
+		links, resp := Client.GetFilePublicLinks()
+		CheckNoError(t, resp)
+		found := false
+		for _, l := range links {
+			if l.Id == link.Id {
+				found = true
+				assert.Equal(t, 1, l.DownloadCount)
+				assert.Equal(t, link.Link, l.Link)
+			}
+		}
+		assert.True(t, found, "the link should be listed until it expires")
+
+		time.Sleep(time.Until(time.Unix(0, link.ExpiresAt*int64(time.Millisecond))) + 100*time.Millisecond)
+		assert.Equal(t, http.StatusGone, download(url), "the link should have expired")
+	})
+
+	t.Run("revocation", func(t *testing.T) {
+		link, resp := Client.CreateFilePublicLink(info.Id, &model.FilePublicLink{})
+		CheckNoError(t, resp)
+		url := link.Url(Client.Url)
+
+		ok, resp := Client.RevokeFilePublicLink(link.Id)
+		CheckNoError(t, resp)
+		assert.True(t, ok)
+		assert.Equal(t, http.StatusGone, download(url), "the link should be revoked")
+
+		_, resp = Client.RevokeFilePublicLink(model.NewId())
+		CheckNotFoundStatus(t, resp)
+
+		// Other users can only revoke links to files in channels that they manage
+		link, resp = Client.CreateFilePublicLink(info.Id, &model.FilePublicLink{})
+		CheckNoError(t, resp)
+
+		client2 := th.CreateClient()
+		th.LoginBasic2WithClient(client2)
+
+		_, resp = client2.GetChannelFilePublicLinks(th.BasicChannel.Id)
+		CheckForbiddenStatus(t, resp)
+		_, resp = client2.RevokeFilePublicLink(link.Id)
+		CheckForbiddenStatus(t, resp)
+		assert.Equal(t, http.StatusOK, download(link.Url(Client.Url)))
+
+		// Channel admins can find the links to files in their channel and revoke them
+		th.MakeUserChannelAdmin(th.BasicUser2, th.BasicChannel)
+		links, resp := client2.GetChannelFilePublicLinks(th.BasicChannel.Id)
+		CheckNoError(t, resp)
+		var listed *model.FilePublicLink
+		for _, l := range links {
+			if l.Id == link.Id {
+				listed = l
+			}
+		}
+		require.NotNil(t, listed, "the link should be listed for the channel admin")
+		assert.Equal(t, "", listed.Link, "only the creator should get the link's URL")
+
+		_, resp = client2.RevokeFilePublicLink(listed.Id)
+		CheckNoError(t, resp)
+		assert.Equal(t, http.StatusGone, download(link.Url(Client.Url)))
+
+		Client.Logout()
+		_, resp = Client.RevokeFilePublicLink(link.Id)
+		CheckUnauthorizedStatus(t, resp)
+		th.LoginBasic()
+	})
+
+	t.Run("wrong file", func(t *testing.T) {
+		link, resp := Client.CreateFilePublicLink(info.Id, &model.FilePublicLink{})
+		CheckNoError(t, resp)
+
+		assert.Equal(t, http.StatusNotFound, download(Client.Url+"/files/"+model.NewId()+"/public?t="+link.Id))
+		assert.Equal(t, http.StatusBadRequest, download(Client.Url+"/files/"+info.Id+"/public?t="+model.NewId()))
+	})
+
+	t.Run("get link reuses the unlimited link", func(t *testing.T) {
+		url1, resp := Client.GetFileLink(info.Id)
+		CheckNoError(t, resp)
+		url2, resp := Client.GetFileLink(info.Id)
+		CheckNoError(t, resp)
+		assert.Equal(t, url1, url2)
+		assert.Contains(t, url1, "?t=")
+	})
+
+	t.Run("legacy links", func(t *testing.T) {
+		legacy := th.App.GeneratePublicLink(Client.Url, info)
+
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.FileSettings.EnableLegacyPublicLinks = true })
+		assert.Equal(t, http.StatusOK, download(legacy))
+
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.FileSettings.EnableLegacyPublicLinks = false })
+		assert.Equal(t, http.StatusBadRequest, download(legacy), "legacy links should only work in compatibility mode")
+
+		link, resp := Client.CreateFilePublicLink(info.Id, &model.FilePublicLink{})
+		CheckNoError(t, resp)
+		assert.Equal(t, http.StatusOK, download(link.Url(Client.Url)), "saved links shouldn't depend on compatibility mode")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		link, resp := Client.CreateFilePublicLink(info.Id, &model.FilePublicLink{})
+		CheckNoError(t, resp)
+
+		th.App.UpdateConfig(func(cfg *model.Config) { cfg.FileSettings.EnablePublicLink = false })
+		defer th.App.UpdateConfig(func(cfg *model.Config) { cfg.FileSettings.EnablePublicLink = true })
+
+		_, resp = Client.CreateFilePublicLink(info.Id, &model.FilePublicLink{})
+		CheckNotImplementedStatus(t, resp)
+		assert.Equal(t, http.StatusNotImplemented, download(link.Url(Client.Url)))
+	})
+}
+
+func TestUserFiles(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	if *th.App.Config().FileSettings.DriverName == "" {
+		t.Skip("skipping because no file driver is enabled")
+	}
+
+	data, err := readTestFile("test.png")
+	require.NoError(t, err)
+
+	upload := func(client *model.Client4, userId string) *model.FileInfo {
+		fileResp, resp := client.UploadFile(data, th.BasicChannel.Id, "test.png")
+		CheckNoError(t, resp)
+		info := fileResp.FileInfos[0]
+
+		_, resp = client.CreatePost(&model.Post{ChannelId: th.BasicChannel.Id, UserId: userId, Message: "file", FileIds: []string{info.Id}})
+		CheckNoError(t, resp)
+		return info
+	}
+
+	first := upload(Client, th.BasicUser.Id)
+	second := upload(Client, th.BasicUser.Id)
+
+	t.Run("list own files", func(t *testing.T) {
+		files, resp := Client.GetUserFiles("me", &model.FileInfoFilter{}, 0, 60)
+		CheckNoError(t, resp)
+		require.Len(t, files.FileInfos, 2)
+		assert.Equal(t, second.Id, files.FileInfos[0].Id, "newest files should be listed first")
+		assert.Equal(t, first.Id, files.FileInfos[1].Id)
+		assert.Equal(t, int64(2), files.TotalCount)
+		assert.Equal(t, first.Size+second.Size, files.TotalSize)
+
+		files, resp = Client.GetUserFiles("me", &model.FileInfoFilter{}, 1, 1)
+		CheckNoError(t, resp)
+		require.Len(t, files.FileInfos, 1)
+		assert.Equal(t, first.Id, files.FileInfos[0].Id)
+		assert.Equal(t, int64(2), files.TotalCount, "the totals should cover every page")
+		assert.Equal(t, first.Size+second.Size, files.TotalSize)
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		files, resp := Client.GetUserFiles("me", &model.FileInfoFilter{ChannelId: th.BasicChannel2.Id}, 0, 60)
+		CheckNoError(t, resp)
+		assert.Len(t, files.FileInfos, 0)
+		assert.Equal(t, int64(0), files.TotalSize)
+
+		files, resp = Client.GetUserFiles("me", &model.FileInfoFilter{TeamId: th.BasicTeam.Id, CreatedAfter: first.CreateAt}, 0, 60)
+		CheckNoError(t, resp)
+		require.Len(t, files.FileInfos, 1)
+		assert.Equal(t, second.Id, files.FileInfos[0].Id)
+		assert.Equal(t, second.Size, files.TotalSize)
+
+		r, err := Client.DoApiGet(Client.GetUserRoute("me")+"/files?created_after=yesterday", "")
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusBadRequest, r.StatusCode)
+	})
+
+	t.Run("other users", func(t *testing.T) {
+		th.LoginBasic2()
+		defer th.LoginBasic()
+
+		_, resp := Client.GetUserFiles(th.BasicUser.Id, &model.FileInfoFilter{}, 0, 60)
+		CheckForbiddenStatus(t, resp)
+
+		_, resp = Client.DeleteUserFile(th.BasicUser.Id, first.Id)
+		CheckForbiddenStatus(t, resp)
+
+		// Users can't delete files uploaded by someone else from their own account either
+		_, resp = Client.DeleteUserFile(th.BasicUser2.Id, first.Id)
+		CheckForbiddenStatus(t, resp)
+
+		files, resp := th.SystemAdminClient.GetUserFiles(th.BasicUser.Id, &model.FileInfoFilter{}, 0, 60)
+		CheckNoError(t, resp)
+		assert.Len(t, files.FileInfos, 2)
+	})
+
+	t.Run("files on other users' posts", func(t *testing.T) {
+		fileResp, resp := Client.UploadFile(data, th.BasicChannel.Id, "test.png")
+		CheckNoError(t, resp)
+		info := fileResp.FileInfos[0]
+		defer th.cleanupTestFile(info)
+
+		// Hacky way to assign file to a post (usually would be done by CreatePost call)
+		post := th.CreatePostWithClient(th.SystemAdminClient, th.BasicChannel)
+		store.Must(th.App.Srv.Store.FileInfo().AttachToPost(info.Id, post.Id))
+
+		_, resp = Client.DeleteUserFile("me", info.Id)
+		CheckForbiddenStatus(t, resp)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		_, resp := Client.DeleteUserFile("me", model.NewId())
+		CheckNotFoundStatus(t, resp)
+
+		ok, resp := Client.DeleteUserFile("me", first.Id)
+		CheckNoError(t, resp)
+		assert.True(t, ok)
+
+		_, resp = Client.GetFileInfo(first.Id)
+		CheckNotFoundStatus(t, resp)
+
+		_, resp = Client.DeleteUserFile("me", first.Id)
+		CheckNotFoundStatus(t, resp)
+
+		ok, resp = th.SystemAdminClient.DeleteUserFile(th.BasicUser.Id, second.Id)
+		CheckNoError(t, resp)
+		assert.True(t, ok)
+
+		files, resp := Client.GetUserFiles("me", &model.FileInfoFilter{}, 0, 60)
+		CheckNoError(t, resp)
+		assert.Len(t, files.FileInfos, 0)
+		assert.Equal(t, int64(0), files.TotalCount)
+	})
+}
+
+func TestFileStorageUsage(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	if *th.App.Config().FileSettings.DriverName == "" {
+		t.Skip("skipping because no file driver is enabled")
+	}
+
+	userStorageQuota := *th.App.Config().FileSettings.UserStorageQuota
+	defer th.App.UpdateConfig(func(cfg *model.Config) { *cfg.FileSettings.UserStorageQuota = userStorageQuota })
+
+	data, err := readTestFile("test.png")
+	require.NoError(t, err)
+
+	fileResp, resp := Client.UploadFile(data, th.BasicChannel.Id, "test.png")
+	CheckNoError(t, resp)
+	defer th.cleanupTestFile(fileResp.FileInfos[0])
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.FileSettings.UserStorageQuota = int64(len(data)) })
+
+	_, resp = Client.UploadFile(data, th.BasicChannel.Id, "test.png")
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	assert.Equal(t, "app.file_storage_usage.user_quota_exceeded.app_error", resp.Error.Id)
+
+	t.Run("top consumers", func(t *testing.T) {
+		_, resp := Client.GetTopFileStorageUsage(model.FILE_STORAGE_USAGE_TYPE_USER, 0, 100)
+		CheckForbiddenStatus(t, resp)
+
+		_, resp = th.SystemAdminClient.GetTopFileStorageUsage("channel", 0, 100)
+		CheckBadRequestStatus(t, resp)
+
+		usages, resp := th.SystemAdminClient.GetTopFileStorageUsage(model.FILE_STORAGE_USAGE_TYPE_USER, 0, 10000)
+		CheckNoError(t, resp)
+		found := false
+		for _, usage := range usages {
+			if usage.Id == th.BasicUser.Id {
+				found = true
+				assert.Equal(t, int64(len(data)), usage.Bytes)
+			}
+		}
+		assert.True(t, found, "the user should be listed")
+	})
+
+	t.Run("exemption", func(t *testing.T) {
+		_, resp := Client.UpdateUserStorageExempt(th.BasicUser.Id, true)
+		CheckForbiddenStatus(t, resp)
+
+		_, resp = th.SystemAdminClient.UpdateUserStorageExempt(model.NewId(), true)
+		CheckNotFoundStatus(t, resp)
+
+		ok, resp := th.SystemAdminClient.UpdateUserStorageExempt(th.BasicUser.Id, true)
+		CheckNoError(t, resp)
+		assert.True(t, ok)
+
+		fileResp, resp := Client.UploadFile(data, th.BasicChannel.Id, "test.png")
+		CheckNoError(t, resp)
+		th.cleanupTestFile(fileResp.FileInfos[0])
+	})
+}
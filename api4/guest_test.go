@@ -0,0 +1,178 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+// loginGuestForTest creates a guest that only belongs to the basic channel, and returns a client logged in as them.
+func loginGuestForTest(t *testing.T, th *TestHelper) (*model.User, *model.Client4) {
+	guest, err := th.App.CreateGuest(&model.User{
+		Email:    th.GenerateTestEmail(),
+		Username: GenerateTestUsername(),
+		Password: "Password1",
+	})
+	require.Nil(t, err)
+	store.Must(th.App.Srv.Store.User().VerifyEmail(guest.Id))
+
+	th.LinkUserToTeam(guest, th.BasicTeam)
+	th.AddUserToChannel(guest, th.BasicChannel)
+
+	client := th.CreateClient()
+	_, resp := client.Login(guest.Email, "Password1")
+	require.Nil(t, resp.Error)
+
+	return guest, client
+}
+
+func TestGuestRestrictions(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+
+	guest, client := loginGuestForTest(t, th)
+
+	outsider := th.CreateUser()
+	th.LinkUserToTeam(outsider, th.BasicTeam)
+
+	t.Run("channels", func(t *testing.T) {
+		_, resp := client.GetPublicChannelsForTeam(th.BasicTeam.Id, 0, 100, "")
+		CheckForbiddenStatus(t, resp)
+
+		_, resp = client.AddChannelMember(th.BasicChannel2.Id, guest.Id)
+		CheckForbiddenStatus(t, resp)
+
+		channels, resp := client.SearchChannels(th.BasicTeam.Id, &model.ChannelSearch{Term: th.BasicChannel.Name})
+		CheckNoError(t, resp)
+		require.Len(t, channels, 1)
+		assert.Equal(t, th.BasicChannel.Id, channels[0].Id)
+
+		channels, resp = client.SearchChannels(th.BasicTeam.Id, &model.ChannelSearch{Term: th.BasicChannel2.Name})
+		CheckNoError(t, resp)
+		assert.Len(t, channels, 0)
+	})
+
+	t.Run("users", func(t *testing.T) {
+		_, resp := client.GetUser(th.BasicUser.Id, "")
+		CheckNoError(t, resp)
+
+		_, resp = client.GetUser(outsider.Id, "")
+		CheckForbiddenStatus(t, resp)
+
+		_, resp = client.GetUserByUsername(outsider.Username, "")
+		CheckForbiddenStatus(t, resp)
+
+		_, resp = client.GetUserByEmail(outsider.Email, "")
+		CheckForbiddenStatus(t, resp)
+
+		_, resp = client.GetUserByEmail(th.BasicUser.Email, "")
+		CheckNoError(t, resp)
+
+		users, resp := client.GetUsersInTeam(th.BasicTeam.Id, 0, 100, "")
+		CheckNoError(t, resp)
+		for _, user := range users {
+			assert.NotEqual(t, outsider.Id, user.Id)
+		}
+
+		_, resp = client.GetUsersNotInTeam(th.BasicTeam.Id, 0, 100, "")
+		CheckForbiddenStatus(t, resp)
+
+		users, resp = client.GetUsersByIds([]string{th.BasicUser.Id, outsider.Id})
+		CheckNoError(t, resp)
+		require.Len(t, users, 1)
+		assert.Equal(t, th.BasicUser.Id, users[0].Id)
+
+		users, resp = client.SearchUsers(&model.UserSearch{Term: outsider.Username, TeamId: th.BasicTeam.Id})
+		CheckNoError(t, resp)
+		assert.Len(t, users, 0)
+
+		_, resp = client.CreateDirectChannel(guest.Id, outsider.Id)
+		CheckForbiddenStatus(t, resp)
+
+		_, resp = client.CreateDirectChannel(guest.Id, th.BasicUser.Id)
+		CheckNoError(t, resp)
+	})
+
+	t.Run("regular users are unaffected", func(t *testing.T) {
+		_, resp := th.Client.GetUser(outsider.Id, "")
+		CheckNoError(t, resp)
+
+		_, resp = th.Client.GetUserByEmail(outsider.Email, "")
+		CheckNoError(t, resp)
+
+		users, resp := th.Client.GetUsersByIds([]string{guest.Id, outsider.Id})
+		CheckNoError(t, resp)
+		assert.Len(t, users, 2)
+	})
+}
+
+func TestInviteGuestsToTeam(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+
+	emails := []string{th.GenerateTestEmail()}
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.GuestAccountsSettings.Enable = false })
+	_, resp := th.SystemAdminClient.InviteGuestsToTeam(th.BasicTeam.Id, emails, []string{th.BasicChannel.Id}, "")
+	CheckNotImplementedStatus(t, resp)
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.GuestAccountsSettings.Enable = true })
+
+	_, resp = th.Client.InviteGuestsToTeam(th.BasicTeam.Id, emails, []string{th.BasicChannel.Id}, "")
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = th.SystemAdminClient.InviteGuestsToTeam(th.BasicTeam.Id, []string{}, []string{th.BasicChannel.Id}, "")
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = th.SystemAdminClient.InviteGuestsToTeam(th.BasicTeam.Id, emails, []string{model.NewId()}, "")
+	CheckNotFoundStatus(t, resp)
+
+	ok, resp := th.SystemAdminClient.InviteGuestsToTeam(th.BasicTeam.Id, emails, []string{th.BasicChannel.Id}, "welcome")
+	CheckNoError(t, resp)
+	assert.True(t, ok)
+}
+
+func TestPromoteAndDemoteGuest(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.GuestAccountsSettings.Enable = true })
+
+	guest, _ := loginGuestForTest(t, th)
+
+	_, resp := th.Client.PromoteGuestToUser(guest.Id)
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = th.SystemAdminClient.DemoteUserToGuest(guest.Id)
+	CheckBadRequestStatus(t, resp)
+
+	ok, resp := th.SystemAdminClient.PromoteGuestToUser(guest.Id)
+	CheckNoError(t, resp)
+	assert.True(t, ok)
+
+	user, resp := th.SystemAdminClient.GetUser(guest.Id, "")
+	CheckNoError(t, resp)
+	assert.False(t, user.IsGuest())
+
+	_, resp = th.Client.DemoteUserToGuest(guest.Id)
+	CheckForbiddenStatus(t, resp)
+
+	ok, resp = th.SystemAdminClient.DemoteUserToGuest(guest.Id)
+	CheckNoError(t, resp)
+	assert.True(t, ok)
+
+	member, resp := th.SystemAdminClient.GetChannelMember(th.BasicChannel.Id, guest.Id, "")
+	CheckNoError(t, resp)
+	assert.Equal(t, model.CHANNEL_GUEST_ROLE_ID, member.Roles)
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.GuestAccountsSettings.Enable = false })
+	_, resp = th.SystemAdminClient.DemoteUserToGuest(th.BasicUser2.Id)
+	CheckNotImplementedStatus(t, resp)
+}
@@ -6,6 +6,7 @@ package api4
 import (
 	"net/http"
 
+	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/web"
 )
 
@@ -19,6 +20,7 @@ func (api *API) ApiHandler(h func(*Context, http.ResponseWriter, *http.Request))
 		TrustRequester: false,
 		RequireMfa:     false,
 		IsStatic:       false,
+		IsLocal:        api.local,
 	}
 }
 
@@ -30,6 +32,7 @@ func (api *API) ApiSessionRequired(h func(*Context, http.ResponseWriter, *http.R
 		TrustRequester: false,
 		RequireMfa:     true,
 		IsStatic:       false,
+		IsLocal:        api.local,
 	}
 }
 
@@ -41,6 +44,22 @@ func (api *API) ApiSessionRequiredMfa(h func(*Context, http.ResponseWriter, *htt
 		TrustRequester: false,
 		RequireMfa:     false,
 		IsStatic:       false,
+		IsLocal:        api.local,
+	}
+}
+
+// ApiPermissionRequired is like ApiSessionRequired, except that the session must also have the given system wide
+// permission before the request is handled.
+func (api *API) ApiPermissionRequired(permission *model.Permission, h func(*Context, http.ResponseWriter, *http.Request)) http.Handler {
+	return &web.Handler{
+		App:               api.App,
+		HandleFunc:        h,
+		RequireSession:    true,
+		TrustRequester:    false,
+		RequireMfa:        true,
+		IsStatic:          false,
+		RequirePermission: permission,
+		IsLocal:           api.local,
 	}
 }
 
@@ -52,6 +71,7 @@ func (api *API) ApiHandlerTrustRequester(h func(*Context, http.ResponseWriter, *
 		TrustRequester: true,
 		RequireMfa:     false,
 		IsStatic:       false,
+		IsLocal:        api.local,
 	}
 }
 
@@ -63,5 +83,6 @@ func (api *API) ApiSessionRequiredTrustRequester(h func(*Context, http.ResponseW
 		TrustRequester: true,
 		RequireMfa:     true,
 		IsStatic:       false,
+		IsLocal:        api.local,
 	}
 }
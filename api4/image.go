@@ -5,6 +5,8 @@ package api4
 
 import (
 	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
 )
 
 func (api *API) InitImage() {
@@ -12,10 +14,32 @@ func (api *API) InitImage() {
 }
 
 func getImage(c *Context, w http.ResponseWriter, r *http.Request) {
+	imageURL := r.URL.Query().Get("url")
+
+	if *c.App.Config().ServiceSettings.ImageProxyType == model.IMAGE_PROXY_TYPE_LOCAL {
+		if err := c.App.VerifyImageProxySignature(imageURL, r.URL.Query().Get("sig")); err != nil {
+			c.Err = err
+			return
+		}
+
+		data, contentType, err := c.App.GetProxiedImage(imageURL)
+		if err != nil {
+			c.Err = err
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Cache-Control", "private, max-age=86400")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Content-Security-Policy", "default-src 'none'")
+		w.Write(data)
+		return
+	}
+
 	// Only redirect to our image proxy if one is enabled. Arbitrary redirects are not allowed for
 	// security reasons.
 	if transform := c.App.ImageProxyAdder(); transform != nil {
-		http.Redirect(w, r, transform(r.URL.Query().Get("url")), http.StatusFound)
+		http.Redirect(w, r, transform(imageURL), http.StatusFound)
 	} else {
 		http.NotFound(w, r)
 	}
@@ -50,4 +50,28 @@ func TestGetImage(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, http.StatusFound, resp.StatusCode)
 	assert.Equal(t, "https://proxy.foo.bar/004afe2ef382eb5f30c4490f793f8a8c5b33d8a2/687474703a2f2f666f6f2e6261722f62617a2e676966", resp.Header.Get("Location"))
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		cfg.ServiceSettings.ImageProxyType = model.NewString(model.IMAGE_PROXY_TYPE_LOCAL)
+		cfg.ServiceSettings.ImageProxyURL = model.NewString("")
+	})
+
+	r, err = http.NewRequest("GET", th.Client.ApiUrl+"/image?url="+url.QueryEscape(originURL)+"&sig=bad", nil)
+	require.NoError(t, err)
+	r.Header.Set(model.HEADER_AUTH, th.Client.AuthType+" "+th.Client.AuthToken)
+
+	resp, err = th.Client.HttpClient.Do(r)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// foo.bar doesn't resolve, so a correctly signed request gets as far as the fetch.
+	signature, appErr := th.App.ImageProxySignature(originURL)
+	require.Nil(t, appErr)
+	r, err = http.NewRequest("GET", th.Client.ApiUrl+"/image?url="+url.QueryEscape(originURL)+"&sig="+signature, nil)
+	require.NoError(t, err)
+	r.Header.Set(model.HEADER_AUTH, th.Client.AuthType+" "+th.Client.AuthToken)
+
+	resp, err = th.Client.HttpClient.Do(r)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
 }
@@ -10,7 +10,6 @@ import (
 	"strings"
 
 	"github.com/mattermost/mattermost-server/app"
-	"github.com/mattermost/mattermost-server/mlog"
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/utils"
 )
@@ -31,6 +30,7 @@ func (api *API) InitOAuth() {
 	api.BaseRoutes.Root.Handle("/oauth/authorize", api.ApiSessionRequired(authorizeOAuthApp)).Methods("POST")
 	api.BaseRoutes.Root.Handle("/oauth/deauthorize", api.ApiSessionRequired(deauthorizeOAuthApp)).Methods("POST")
 	api.BaseRoutes.Root.Handle("/oauth/access_token", api.ApiHandlerTrustRequester(getAccessToken)).Methods("POST")
+	api.BaseRoutes.Root.Handle("/oauth/introspect", api.ApiHandlerTrustRequester(introspectOAuthToken)).Methods("POST")
 
 	// API version independent OAuth as a client endpoints
 	api.BaseRoutes.Root.Handle("/oauth/{service:[A-Za-z0-9]+}/complete", api.ApiHandler(completeOAuth)).Methods("GET")
@@ -433,6 +433,40 @@ func getAccessToken(c *Context, w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(accessRsp.ToJson()))
 }
 
+func introspectOAuthToken(c *Context, w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	// client credentials can be sent using HTTP basic authentication or in the body as with the access token endpoint
+	clientId, secret, ok := r.BasicAuth()
+	if !ok {
+		clientId = r.FormValue("client_id")
+		secret = r.FormValue("client_secret")
+	}
+
+	if len(clientId) != 26 || len(secret) == 0 {
+		c.Err = model.NewAppError("introspectOAuthToken", "api.oauth.introspect.credentials.app_error", nil, "", http.StatusUnauthorized)
+		return
+	}
+
+	token := r.FormValue("token")
+	if len(token) == 0 {
+		c.SetInvalidParam("token")
+		return
+	}
+
+	introspection, err := c.App.IntrospectOAuthToken(clientId, secret, token, r.FormValue("token_type_hint"))
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	w.Write([]byte(introspection.ToJson()))
+}
+
 func completeOAuth(c *Context, w http.ResponseWriter, r *http.Request) {
 	c.RequireService()
 	if c.Err != nil {
@@ -463,7 +497,8 @@ func completeOAuth(c *Context, w http.ResponseWriter, r *http.Request) {
 
 	if err != nil {
 		err.Translate(c.T)
-		mlog.Error(err.Error())
+		err.RequestId = c.RequestId
+		c.Log.Error(err.Error())
 		if action == model.OAUTH_ACTION_MOBILE {
 			w.Write([]byte(err.ToJson()))
 		} else {
@@ -475,7 +510,8 @@ func completeOAuth(c *Context, w http.ResponseWriter, r *http.Request) {
 	user, err := c.App.CompleteOAuth(service, body, teamId, props)
 	if err != nil {
 		err.Translate(c.T)
-		mlog.Error(err.Error())
+		err.RequestId = c.RequestId
+		c.Log.Error(err.Error())
 		if action == model.OAUTH_ACTION_MOBILE {
 			w.Write([]byte(err.ToJson()))
 		} else {
@@ -494,6 +530,7 @@ func completeOAuth(c *Context, w http.ResponseWriter, r *http.Request) {
 		session, err := c.App.DoLogin(w, r, user, "")
 		if err != nil {
 			err.Translate(c.T)
+			err.RequestId = c.RequestId
 			c.Err = err
 			if action == model.OAUTH_ACTION_MOBILE {
 				w.Write([]byte(err.ToJson()))
@@ -13,6 +13,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/mattermost/mattermost-server/einterfaces"
 	"github.com/mattermost/mattermost-server/model"
@@ -948,6 +949,223 @@ func TestOAuthAccessToken(t *testing.T) {
 	Client.ClearOAuthToken()
 }
 
+// getOAuthTokensForTest authorizes the app for the client's user and exchanges the code for an access token.
+func getOAuthTokensForTest(t *testing.T, Client *model.Client4, oauthApp *model.OAuthApp) *model.AccessResponse {
+	t.Helper()
+
+	authRequest := &model.AuthorizeRequest{
+		ResponseType: model.AUTHCODE_RESPONSE_TYPE,
+		ClientId:     oauthApp.Id,
+		RedirectUri:  oauthApp.CallbackUrls[0],
+		Scope:        "all",
+		State:        "123",
+	}
+
+	redirect, resp := Client.AuthorizeOAuthApp(authRequest)
+	CheckNoError(t, resp)
+	rurl, _ := url.Parse(redirect)
+
+	data := url.Values{"grant_type": []string{model.ACCESS_TOKEN_GRANT_TYPE}, "client_id": []string{oauthApp.Id}, "client_secret": []string{oauthApp.ClientSecret}, "code": []string{rurl.Query().Get("code")}, "redirect_uri": []string{oauthApp.CallbackUrls[0]}}
+
+	rsp, resp := Client.GetOAuthAccessToken(data)
+	CheckNoError(t, resp)
+
+	return rsp
+}
+
+func refreshOAuthTokenForTest(Client *model.Client4, oauthApp *model.OAuthApp, refreshToken string) (*model.AccessResponse, *model.Response) {
+	data := url.Values{"grant_type": []string{model.REFRESH_TOKEN_GRANT_TYPE}, "client_id": []string{oauthApp.Id}, "client_secret": []string{oauthApp.ClientSecret}, "refresh_token": []string{refreshToken}, "redirect_uri": []string{oauthApp.CallbackUrls[0]}}
+	return Client.GetOAuthAccessToken(data)
+}
+
+func TestOAuthRefreshTokenRotation(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	Client := th.Client
+
+	enableOAuth := th.App.Config().ServiceSettings.EnableOAuthServiceProvider
+	defer func() {
+		th.App.UpdateConfig(func(cfg *model.Config) { cfg.ServiceSettings.EnableOAuthServiceProvider = enableOAuth })
+	}()
+	th.App.UpdateConfig(func(cfg *model.Config) { cfg.ServiceSettings.EnableOAuthServiceProvider = true })
+
+	defaultRolePermissions := th.SaveDefaultRolePermissions()
+	defer func() {
+		th.RestoreDefaultRolePermissions(defaultRolePermissions)
+	}()
+	th.AddPermissionToRole(model.PERMISSION_MANAGE_OAUTH.Id, model.SYSTEM_USER_ROLE_ID)
+
+	t.Run("reusing a refresh token revokes the grant", func(t *testing.T) {
+		oauthApp := &model.OAuthApp{Name: "TestApp5" + model.NewId(), Homepage: "https://nowhere.com", Description: "test", CallbackUrls: []string{"https://nowhere.com"}}
+		oauthApp = Client.Must(Client.CreateOAuthApp(oauthApp)).(*model.OAuthApp)
+
+		first := getOAuthTokensForTest(t, Client, oauthApp)
+
+		second, resp := refreshOAuthTokenForTest(Client, oauthApp, first.RefreshToken)
+		CheckNoError(t, resp)
+		assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+
+		_, resp = refreshOAuthTokenForTest(Client, oauthApp, first.RefreshToken)
+		CheckBadRequestStatus(t, resp)
+		CheckErrorMessage(t, resp, "api.oauth.get_access_token.refresh_token_reused.app_error")
+
+		// the newest tokens were revoked along with the rest of the grant
+		_, resp = refreshOAuthTokenForTest(Client, oauthApp, second.RefreshToken)
+		CheckNotFoundStatus(t, resp)
+
+		oauthClient := th.CreateClient()
+		oauthClient.SetOAuthToken(second.AccessToken)
+		_, resp = oauthClient.GetMe("")
+		CheckUnauthorizedStatus(t, resp)
+	})
+
+	t.Run("legacy apps can reuse refresh tokens without revoking the grant", func(t *testing.T) {
+		oauthApp := &model.OAuthApp{Name: "TestApp5" + model.NewId(), Homepage: "https://nowhere.com", Description: "test", CallbackUrls: []string{"https://nowhere.com"}, LegacyRefreshTokens: true}
+		oauthApp = Client.Must(Client.CreateOAuthApp(oauthApp)).(*model.OAuthApp)
+		require.True(t, oauthApp.LegacyRefreshTokens)
+
+		first := getOAuthTokensForTest(t, Client, oauthApp)
+
+		second, resp := refreshOAuthTokenForTest(Client, oauthApp, first.RefreshToken)
+		CheckNoError(t, resp)
+
+		_, resp = refreshOAuthTokenForTest(Client, oauthApp, first.RefreshToken)
+		CheckNotFoundStatus(t, resp)
+
+		_, resp = refreshOAuthTokenForTest(Client, oauthApp, second.RefreshToken)
+		CheckNoError(t, resp)
+	})
+
+	t.Run("refresh tokens can't be used by other apps", func(t *testing.T) {
+		oauthApp := &model.OAuthApp{Name: "TestApp5" + model.NewId(), Homepage: "https://nowhere.com", Description: "test", CallbackUrls: []string{"https://nowhere.com"}}
+		oauthApp = Client.Must(Client.CreateOAuthApp(oauthApp)).(*model.OAuthApp)
+		otherApp := &model.OAuthApp{Name: "TestApp5" + model.NewId(), Homepage: "https://nowhere.com", Description: "test", CallbackUrls: []string{"https://nowhere.com"}}
+		otherApp = Client.Must(Client.CreateOAuthApp(otherApp)).(*model.OAuthApp)
+
+		tokens := getOAuthTokensForTest(t, Client, oauthApp)
+
+		_, resp := refreshOAuthTokenForTest(Client, otherApp, tokens.RefreshToken)
+		CheckNotFoundStatus(t, resp)
+
+		_, resp = refreshOAuthTokenForTest(Client, oauthApp, tokens.RefreshToken)
+		CheckNoError(t, resp)
+	})
+
+	t.Run("access token lifetime", func(t *testing.T) {
+		oauthApp := &model.OAuthApp{Name: "TestApp5" + model.NewId(), Homepage: "https://nowhere.com", Description: "test", CallbackUrls: []string{"https://nowhere.com"}, AccessTokenExpiresIn: 60 * 60}
+		oauthApp = Client.Must(Client.CreateOAuthApp(oauthApp)).(*model.OAuthApp)
+
+		tokens := getOAuthTokensForTest(t, Client, oauthApp)
+		assert.Equal(t, int32(60*60), tokens.ExpiresIn)
+
+		session, err := th.App.GetSession(tokens.AccessToken)
+		require.Nil(t, err)
+		assert.True(t, session.ExpiresAt <= model.GetMillis()+60*60*1000)
+
+		tokens, resp := refreshOAuthTokenForTest(Client, oauthApp, tokens.RefreshToken)
+		CheckNoError(t, resp)
+		assert.Equal(t, int32(60*60), tokens.ExpiresIn)
+
+		oauthApp.AccessTokenExpiresIn = -1
+		_, resp = Client.UpdateOAuthApp(oauthApp)
+		CheckBadRequestStatus(t, resp)
+	})
+}
+
+func TestOAuthIntrospect(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+
+	Client := th.Client
+
+	enableOAuth := th.App.Config().ServiceSettings.EnableOAuthServiceProvider
+	defer func() {
+		th.App.UpdateConfig(func(cfg *model.Config) { cfg.ServiceSettings.EnableOAuthServiceProvider = enableOAuth })
+	}()
+	th.App.UpdateConfig(func(cfg *model.Config) { cfg.ServiceSettings.EnableOAuthServiceProvider = true })
+
+	defaultRolePermissions := th.SaveDefaultRolePermissions()
+	defer func() {
+		th.RestoreDefaultRolePermissions(defaultRolePermissions)
+	}()
+	th.AddPermissionToRole(model.PERMISSION_MANAGE_OAUTH.Id, model.SYSTEM_USER_ROLE_ID)
+
+	oauthApp := &model.OAuthApp{Name: "TestApp5" + model.NewId(), Homepage: "https://nowhere.com", Description: "test", CallbackUrls: []string{"https://nowhere.com"}}
+	oauthApp = Client.Must(Client.CreateOAuthApp(oauthApp)).(*model.OAuthApp)
+	otherApp := &model.OAuthApp{Name: "TestApp5" + model.NewId(), Homepage: "https://nowhere.com", Description: "test", CallbackUrls: []string{"https://nowhere.com"}}
+	otherApp = Client.Must(Client.CreateOAuthApp(otherApp)).(*model.OAuthApp)
+	trustedApp := &model.OAuthApp{Name: "TestApp5" + model.NewId(), Homepage: "https://nowhere.com", Description: "test", CallbackUrls: []string{"https://nowhere.com"}, IsTrusted: true}
+	trustedApp = th.SystemAdminClient.Must(th.SystemAdminClient.CreateOAuthApp(trustedApp)).(*model.OAuthApp)
+
+	tokens := getOAuthTokensForTest(t, Client, oauthApp)
+
+	t.Run("access token", func(t *testing.T) {
+		introspection, resp := Client.IntrospectOAuthToken(oauthApp.Id, oauthApp.ClientSecret, tokens.AccessToken)
+		CheckNoError(t, resp)
+		assert.True(t, introspection.Active)
+		assert.Equal(t, oauthApp.Id, introspection.ClientId)
+		assert.Equal(t, th.BasicUser.Id, introspection.Subject)
+		assert.Equal(t, th.BasicUser.Username, introspection.Username)
+		assert.Equal(t, model.ACCESS_TOKEN_TYPE, introspection.TokenType)
+		assert.Equal(t, "all", introspection.Scope)
+		assert.True(t, introspection.ExpiresAt > introspection.IssuedAt)
+	})
+
+	t.Run("refresh token", func(t *testing.T) {
+		introspection, resp := Client.IntrospectOAuthToken(oauthApp.Id, oauthApp.ClientSecret, tokens.RefreshToken)
+		CheckNoError(t, resp)
+		assert.True(t, introspection.Active)
+		assert.Equal(t, th.BasicUser.Id, introspection.Subject)
+		assert.Equal(t, "", introspection.TokenType)
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		introspection, resp := Client.IntrospectOAuthToken(oauthApp.Id, oauthApp.ClientSecret, model.NewId())
+		CheckNoError(t, resp)
+		assert.False(t, introspection.Active)
+	})
+
+	t.Run("tokens issued to other apps", func(t *testing.T) {
+		introspection, resp := Client.IntrospectOAuthToken(otherApp.Id, otherApp.ClientSecret, tokens.AccessToken)
+		CheckNoError(t, resp)
+		assert.False(t, introspection.Active)
+		assert.Equal(t, "", introspection.Subject)
+
+		introspection, resp = Client.IntrospectOAuthToken(trustedApp.Id, trustedApp.ClientSecret, tokens.AccessToken)
+		CheckNoError(t, resp)
+		assert.True(t, introspection.Active)
+	})
+
+	t.Run("bad credentials", func(t *testing.T) {
+		_, resp := Client.IntrospectOAuthToken(oauthApp.Id, "junk", tokens.AccessToken)
+		CheckUnauthorizedStatus(t, resp)
+
+		_, resp = Client.IntrospectOAuthToken(model.NewId(), oauthApp.ClientSecret, tokens.AccessToken)
+		CheckUnauthorizedStatus(t, resp)
+	})
+
+	t.Run("revoked token", func(t *testing.T) {
+		require.Nil(t, th.App.RevokeAccessToken(tokens.AccessToken))
+
+		introspection, resp := Client.IntrospectOAuthToken(oauthApp.Id, oauthApp.ClientSecret, tokens.AccessToken)
+		CheckNoError(t, resp)
+		assert.False(t, introspection.Active)
+
+		introspection, resp = Client.IntrospectOAuthToken(oauthApp.Id, oauthApp.ClientSecret, tokens.RefreshToken)
+		CheckNoError(t, resp)
+		assert.False(t, introspection.Active)
+	})
+
+	t.Run("turned off", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) { cfg.ServiceSettings.EnableOAuthServiceProvider = false })
+		defer th.App.UpdateConfig(func(cfg *model.Config) { cfg.ServiceSettings.EnableOAuthServiceProvider = true })
+
+		_, resp := Client.IntrospectOAuthToken(oauthApp.Id, oauthApp.ClientSecret, tokens.AccessToken)
+		CheckNotImplementedStatus(t, resp)
+	})
+}
+
 func TestOAuthComplete(t *testing.T) {
 	if testing.Short() {
 		t.SkipNow()
@@ -5,8 +5,13 @@ package api4
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
@@ -198,3 +203,133 @@ func TestPlugin(t *testing.T) {
 	_, resp = th.SystemAdminClient.RemovePlugin("bad.id")
 	CheckBadRequestStatus(t, resp)
 }
+
+func TestInstallPluginFromURL(t *testing.T) {
+	pluginDir, err := ioutil.TempDir("", "mm-plugin-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(pluginDir)
+
+	webappDir, err := ioutil.TempDir("", "mm-webapp-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(webappDir)
+
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.PluginSettings.Enable = true
+		*cfg.PluginSettings.EnableUploads = true
+	})
+
+	th.App.InitPlugins(pluginDir, webappDir, nil)
+	defer func() {
+		th.App.ShutDownPlugins()
+		th.App.PluginEnv = nil
+	}()
+
+	path, _ := utils.FindDir("tests")
+	bundle, err := ioutil.ReadFile(filepath.Join(path, "testplugin.tar.gz"))
+	require.NoError(t, err)
+	checksum := sha256.Sum256(bundle)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/plugin.tar.gz":
+			w.Write(bundle)
+		case "/notaplugin.tar.gz":
+			w.Write([]byte("badfile"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	caFile, err := ioutil.TempFile("", "mm-plugin-ca")
+	require.NoError(t, err)
+	defer os.Remove(caFile.Name())
+	require.NoError(t, pem.Encode(caFile, &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	require.NoError(t, caFile.Close())
+
+	request := &model.InstallPluginFromURLRequest{
+		URL:    server.URL + "/plugin.tar.gz",
+		SHA256: hex.EncodeToString(checksum[:]),
+	}
+
+	t.Run("untrusted certificate", func(t *testing.T) {
+		_, resp := th.SystemAdminClient.InstallPluginFromURL(request)
+		CheckBadRequestStatus(t, resp)
+		CheckErrorMessage(t, resp, "app.plugin.install_from_url.download.app_error")
+	})
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.PluginSettings.InstallFromURLCACertificateFile = caFile.Name() })
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		wrong := sha256.Sum256([]byte("something else"))
+		_, resp := th.SystemAdminClient.InstallPluginFromURL(&model.InstallPluginFromURLRequest{
+			URL:    request.URL,
+			SHA256: hex.EncodeToString(wrong[:]),
+		})
+		CheckBadRequestStatus(t, resp)
+		CheckErrorMessage(t, resp, "app.plugin.install_from_url.checksum.app_error")
+	})
+
+	t.Run("oversized bundle", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.PluginSettings.InstallFromURLMaxFileSize = int64(len(bundle) - 1) })
+		defer th.App.UpdateConfig(func(cfg *model.Config) {
+			*cfg.PluginSettings.InstallFromURLMaxFileSize = model.PLUGIN_SETTINGS_DEFAULT_INSTALL_FROM_URL_MAX_FILE_SIZE
+		})
+
+		_, resp := th.SystemAdminClient.InstallPluginFromURL(request)
+		CheckErrorMessage(t, resp, "app.plugin.install_from_url.too_large.app_error")
+		assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	})
+
+	t.Run("download failure", func(t *testing.T) {
+		_, resp := th.SystemAdminClient.InstallPluginFromURL(&model.InstallPluginFromURLRequest{
+			URL:    server.URL + "/missing.tar.gz",
+			SHA256: request.SHA256,
+		})
+		CheckBadRequestStatus(t, resp)
+		CheckErrorMessage(t, resp, "app.plugin.install_from_url.download_status.app_error")
+	})
+
+	t.Run("not a plugin", func(t *testing.T) {
+		badChecksum := sha256.Sum256([]byte("badfile"))
+		_, resp := th.SystemAdminClient.InstallPluginFromURL(&model.InstallPluginFromURLRequest{
+			URL:    server.URL + "/notaplugin.tar.gz",
+			SHA256: hex.EncodeToString(badChecksum[:]),
+		})
+		CheckBadRequestStatus(t, resp)
+		CheckErrorMessage(t, resp, "app.plugin.extract.app_error")
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		_, resp := th.SystemAdminClient.InstallPluginFromURL(&model.InstallPluginFromURLRequest{URL: "ftp://example.com/plugin.tar.gz", SHA256: request.SHA256})
+		CheckBadRequestStatus(t, resp)
+
+		_, resp = th.SystemAdminClient.InstallPluginFromURL(&model.InstallPluginFromURLRequest{URL: request.URL, SHA256: "abc"})
+		CheckBadRequestStatus(t, resp)
+	})
+
+	t.Run("no permission", func(t *testing.T) {
+		_, resp := th.Client.InstallPluginFromURL(request)
+		CheckForbiddenStatus(t, resp)
+	})
+
+	t.Run("uploads disabled", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.PluginSettings.EnableUploads = false })
+		defer th.App.UpdateConfig(func(cfg *model.Config) { *cfg.PluginSettings.EnableUploads = true })
+
+		_, resp := th.SystemAdminClient.InstallPluginFromURL(request)
+		CheckNotImplementedStatus(t, resp)
+	})
+
+	manifest, resp := th.SystemAdminClient.InstallPluginFromURL(request)
+	CheckNoError(t, resp)
+	CheckCreatedStatus(t, resp)
+	assert.Equal(t, "testplugin", manifest.Id)
+
+	ok, resp := th.SystemAdminClient.RemovePlugin(manifest.Id)
+	CheckNoError(t, resp)
+	assert.True(t, ok)
+}
@@ -5,10 +5,12 @@ package api4
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/mattermost/mattermost-server/mlog"
 	"github.com/mattermost/mattermost-server/model"
 )
 
@@ -19,8 +21,12 @@ func (api *API) InitPost() {
 	api.BaseRoutes.Posts.Handle("/ephemeral", api.ApiSessionRequired(createEphemeralPost)).Methods("POST")
 	api.BaseRoutes.Post.Handle("/thread", api.ApiSessionRequired(getPostThread)).Methods("GET")
 	api.BaseRoutes.Post.Handle("/files/info", api.ApiSessionRequired(getFileInfosForPost)).Methods("GET")
+	api.BaseRoutes.Post.Handle("/history", api.ApiSessionRequired(getPostHistory)).Methods("GET")
+	api.BaseRoutes.Post.Handle("/forward", api.ApiSessionRequired(forwardPost)).Methods("POST")
 	api.BaseRoutes.PostsForChannel.Handle("", api.ApiSessionRequired(getPostsForChannel)).Methods("GET")
 	api.BaseRoutes.PostsForUser.Handle("/flagged", api.ApiSessionRequired(getFlaggedPostsForUser)).Methods("GET")
+	api.BaseRoutes.PostForUser.Handle("/set_unread", api.ApiSessionRequired(setPostUnread)).Methods("POST")
+	api.BaseRoutes.PostForUser.Handle("/set_read", api.ApiSessionRequired(setPostRead)).Methods("POST")
 
 	api.BaseRoutes.Team.Handle("/posts/search", api.ApiSessionRequired(searchPosts)).Methods("POST")
 	api.BaseRoutes.Post.Handle("", api.ApiSessionRequired(updatePost)).Methods("PUT")
@@ -30,6 +36,40 @@ func (api *API) InitPost() {
 	api.BaseRoutes.Post.Handle("/unpin", api.ApiSessionRequired(unpinPost)).Methods("POST")
 }
 
+func sessionCanCreatePostInChannel(c *Context, channelId string) bool {
+	if c.App.SessionHasPermissionToChannel(c.Session, channelId, model.PERMISSION_CREATE_POST) {
+		return true
+	}
+
+	if channel, err := c.App.GetChannel(channelId); err == nil {
+		// Temporary permission check method until advanced permissions, please do not copy
+		if channel.Type == model.CHANNEL_OPEN && !c.App.IsChannelModerated(channel.Id, model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS, c.Session.IsGuest()) && c.App.SessionHasPermissionToTeam(c.Session, channel.TeamId, model.PERMISSION_CREATE_POST_PUBLIC) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// postWithMetadata returns post with the metadata for the session's user, including the extended metadata if the
+// client asked for it. Older clients don't know about the extended metadata, so it's left out for them.
+func postWithMetadata(c *Context, post *model.Post) *model.Post {
+	if c.Params.IncludeMetadata {
+		return c.App.PostWithExtendedMetadata(post, c.Session.UserId)
+	}
+
+	return c.App.PostWithMetadata(post, c.Session.UserId)
+}
+
+// postListWithMetadata is like postWithMetadata, but for every post in list.
+func postListWithMetadata(c *Context, list *model.PostList) *model.PostList {
+	if c.Params.IncludeMetadata {
+		return c.App.PostListWithExtendedMetadata(list, c.Session.UserId)
+	}
+
+	return c.App.PostListWithMetadata(list, c.Session.UserId)
+}
+
 func createPost(c *Context, w http.ResponseWriter, r *http.Request) {
 	post := model.PostFromJson(r.Body)
 	if post == nil {
@@ -38,19 +78,10 @@ func createPost(c *Context, w http.ResponseWriter, r *http.Request) {
 	}
 
 	post.UserId = c.Session.UserId
+	post.RequestId = c.RequestId
 
-	hasPermission := false
-	if c.App.SessionHasPermissionToChannel(c.Session, post.ChannelId, model.PERMISSION_CREATE_POST) {
-		hasPermission = true
-	} else if channel, err := c.App.GetChannel(post.ChannelId); err == nil {
-		// Temporary permission check method until advanced permissions, please do not copy
-		if channel.Type == model.CHANNEL_OPEN && c.App.SessionHasPermissionToTeam(c.Session, channel.TeamId, model.PERMISSION_CREATE_POST_PUBLIC) {
-			hasPermission = true
-		}
-	}
-
-	if !hasPermission {
-		c.SetPermissionError(model.PERMISSION_CREATE_POST)
+	if !sessionCanCreatePostInChannel(c, post.ChannelId) {
+		setChannelPermissionError(c, post.ChannelId, model.PERMISSION_CREATE_POST)
 		return
 	}
 
@@ -58,16 +89,29 @@ func createPost(c *Context, w http.ResponseWriter, r *http.Request) {
 		post.CreateAt = 0
 	}
 
-	rp, err := c.App.CreatePostAsUser(c.App.PostWithProxyRemovedFromImageURLs(post))
+	// @here, @channel and @all don't notify anyone in large channels, so the author has to confirm such posts
+	if r.URL.Query().Get("confirm_channel_mentions") != "true" {
+		if err := c.App.CheckChannelMentionsLimit(post); err != nil {
+			c.Err = err
+			return
+		}
+	}
+
+	// Retries of a request that already created the post get that post back
+	rp, created, err := c.App.CreatePostAsUserIdempotent(c.App.PostWithProxyRemovedFromImageURLs(post))
 	if err != nil {
 		c.Err = err
 		return
 	}
 
-	c.App.SetStatusOnline(c.Session.UserId, c.Session.Id, false)
-	c.App.UpdateLastActivityAtIfNeeded(c.Session)
+	if !c.Session.IsImpersonation {
+		c.App.SetStatusOnline(c.Session.UserId, c.Session.Id, false)
+		c.App.UpdateLastActivityAtIfNeeded(c.Session)
+	}
 
-	w.WriteHeader(http.StatusCreated)
+	if created {
+		w.WriteHeader(http.StatusCreated)
+	}
 	w.Write([]byte(c.App.PostWithProxyAddedToImageURLs(rp).ToJson()))
 }
 
@@ -100,7 +144,7 @@ func createEphemeralPost(c *Context, w http.ResponseWriter, r *http.Request) {
 }
 
 func getPostsForChannel(c *Context, w http.ResponseWriter, r *http.Request) {
-	c.RequireChannelId()
+	c.RequireChannelId().RequireAllowedFields(model.POST_SPARSE_FIELDS)
 	if c.Err != nil {
 		return
 	}
@@ -120,9 +164,12 @@ func getPostsForChannel(c *Context, w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	var historyEnd int64
 	if !c.App.SessionHasPermissionToChannel(c.Session, c.Params.ChannelId, model.PERMISSION_READ_CHANNEL) {
-		c.SetPermissionError(model.PERMISSION_READ_CHANNEL)
-		return
+		if historyEnd = removedGroupChannelMemberHistoryEnd(c, c.Params.ChannelId); historyEnd == 0 {
+			c.SetPermissionError(model.PERMISSION_READ_CHANNEL)
+			return
+		}
 	}
 
 	var list *model.PostList
@@ -162,10 +209,81 @@ func getPostsForChannel(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if historyEnd > 0 {
+		list.RemovePostsCreatedAfter(historyEnd)
+	}
+
 	if len(etag) > 0 {
 		w.Header().Set(model.HEADER_ETAG_SERVER, etag)
 	}
-	w.Write([]byte(c.App.PostListWithProxyAddedToImageURLs(list).ToJson()))
+	w.Write([]byte(c.App.PostListWithProxyAddedToImageURLs(postListWithMetadata(c, list)).ToSparseJson(c.Params.Fields)))
+}
+
+// removedGroupChannelMemberHistoryEnd returns when the session's user was removed from the channel if it's a group
+// channel whose earlier posts they can still read, or 0 otherwise.
+func removedGroupChannelMemberHistoryEnd(c *Context, channelId string) int64 {
+	channel, err := c.App.GetChannel(channelId)
+	if err != nil {
+		return 0
+	}
+
+	historyEnd, err := c.App.GetRemovedGroupChannelMemberHistoryEnd(channel, c.Session.UserId)
+	if err != nil {
+		mlog.Warn(fmt.Sprintf("Failed to get when a user was removed from a group channel: %v", err.Error()), mlog.String("channel_id", channelId))
+		return 0
+	}
+
+	return historyEnd
+}
+
+func setPostUnread(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId().RequirePostId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionToUser(c.Session, c.Params.UserId) {
+		c.SetPermissionError(model.PERMISSION_EDIT_OTHER_USERS)
+		return
+	}
+
+	if !c.App.SessionHasPermissionToChannelByPost(c.Session, c.Params.PostId, model.PERMISSION_READ_CHANNEL) {
+		c.SetPermissionError(model.PERMISSION_READ_CHANNEL)
+		return
+	}
+
+	channelUnread, err := c.App.MarkPostAsUnread(c.Params.PostId, c.Params.UserId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(channelUnread.ToJson()))
+}
+
+func setPostRead(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId().RequirePostId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionToUser(c.Session, c.Params.UserId) {
+		c.SetPermissionError(model.PERMISSION_EDIT_OTHER_USERS)
+		return
+	}
+
+	if !c.App.SessionHasPermissionToChannelByPost(c.Session, c.Params.PostId, model.PERMISSION_READ_CHANNEL) {
+		c.SetPermissionError(model.PERMISSION_READ_CHANNEL)
+		return
+	}
+
+	channelUnread, err := c.App.MarkPostAsRead(c.Params.PostId, c.Params.UserId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(channelUnread.ToJson()))
 }
 
 func getFlaggedPostsForUser(c *Context, w http.ResponseWriter, r *http.Request) {
@@ -198,7 +316,7 @@ func getFlaggedPostsForUser(c *Context, w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	w.Write([]byte(c.App.PostListWithProxyAddedToImageURLs(posts).ToJson()))
+	w.Write([]byte(c.App.PostListWithProxyAddedToImageURLs(postListWithMetadata(c, posts)).ToJson()))
 }
 
 func getPost(c *Context, w http.ResponseWriter, r *http.Request) {
@@ -226,7 +344,7 @@ func getPost(c *Context, w http.ResponseWriter, r *http.Request) {
 				c.SetPermissionError(model.PERMISSION_READ_PUBLIC_CHANNEL)
 				return
 			}
-		} else {
+		} else if historyEnd := removedGroupChannelMemberHistoryEnd(c, channel.Id); historyEnd == 0 || post.CreateAt > historyEnd {
 			c.SetPermissionError(model.PERMISSION_READ_CHANNEL)
 			return
 		}
@@ -236,7 +354,7 @@ func getPost(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	} else {
 		w.Header().Set(model.HEADER_ETAG_SERVER, post.Etag())
-		w.Write([]byte(c.App.PostWithProxyAddedToImageURLs(post).ToJson()))
+		w.Write([]byte(c.App.PostWithProxyAddedToImageURLs(postWithMetadata(c, post)).ToJson()))
 	}
 }
 
@@ -251,6 +369,17 @@ func deletePost(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	post, err := c.App.GetSinglePost(c.Params.PostId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	if err := c.App.CheckPostDeleteTimeLimit(c.Session.UserId, post); err != nil {
+		c.Err = err
+		return
+	}
+
 	if _, err := c.App.DeletePost(c.Params.PostId); err != nil {
 		c.Err = err
 		return
@@ -259,12 +388,51 @@ func deletePost(c *Context, w http.ResponseWriter, r *http.Request) {
 	ReturnStatusOK(w)
 }
 
-func getPostThread(c *Context, w http.ResponseWriter, r *http.Request) {
+func forwardPost(c *Context, w http.ResponseWriter, r *http.Request) {
 	c.RequirePostId()
 	if c.Err != nil {
 		return
 	}
 
+	forward := model.PostForwardFromJson(r.Body)
+	if forward == nil {
+		c.SetInvalidParam("forward")
+		return
+	}
+
+	if len(forward.ChannelId) != 26 {
+		c.SetInvalidParam("channel_id")
+		return
+	}
+
+	if !c.App.SessionHasPermissionToChannelByPost(c.Session, c.Params.PostId, model.PERMISSION_READ_CHANNEL) {
+		c.SetPermissionError(model.PERMISSION_READ_CHANNEL)
+		return
+	}
+
+	if !sessionCanCreatePostInChannel(c, forward.ChannelId) {
+		setChannelPermissionError(c, forward.ChannelId, model.PERMISSION_CREATE_POST)
+		return
+	}
+
+	post, err := c.App.ForwardPost(c.Params.PostId, forward, c.Session.UserId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("post_id=" + c.Params.PostId + " channel_id=" + forward.ChannelId)
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(c.App.PostWithProxyAddedToImageURLs(post).ToJson()))
+}
+
+func getPostThread(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequirePostId().RequireAllowedFields(model.POST_SPARSE_FIELDS)
+	if c.Err != nil {
+		return
+	}
+
 	var list *model.PostList
 	var err *model.AppError
 	if list, err = c.App.GetPostThread(c.Params.PostId); err != nil {
@@ -302,7 +470,7 @@ func getPostThread(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	} else {
 		w.Header().Set(model.HEADER_ETAG_SERVER, list.Etag())
-		w.Write([]byte(c.App.PostListWithProxyAddedToImageURLs(list).ToJson()))
+		w.Write([]byte(c.App.PostListWithProxyAddedToImageURLs(postListWithMetadata(c, list)).ToSparseJson(c.Params.Fields)))
 	}
 }
 
@@ -326,9 +494,35 @@ func searchPosts(c *Context, w http.ResponseWriter, r *http.Request) {
 
 	isOrSearch, _ := props["is_or_search"].(bool)
 
+	page := 0
+	if val, ok := props["page"].(float64); ok {
+		if val < 0 {
+			c.SetInvalidParam("page")
+			return
+		}
+		page = int(val)
+	}
+
+	perPage := model.POST_SEARCH_DEFAULT_PER_PAGE
+	if val, ok := props["per_page"].(float64); ok {
+		if val < 1 {
+			c.SetInvalidParam("per_page")
+			return
+		}
+		perPage = int(val)
+		if perPage > model.POST_SEARCH_MAX_PER_PAGE {
+			perPage = model.POST_SEARCH_MAX_PER_PAGE
+		}
+	}
+
+	var searchAt int64
+	if val, ok := props["search_at"].(float64); ok {
+		searchAt = int64(val)
+	}
+
 	startTime := time.Now()
 
-	posts, err := c.App.SearchPostsInTeam(terms, c.Session.UserId, c.Params.TeamId, isOrSearch)
+	results, err := c.App.SearchPostsInTeam(terms, c.Session.UserId, c.Params.TeamId, isOrSearch, searchAt, page, perPage)
 
 	elapsedTime := float64(time.Since(startTime)) / float64(time.Second)
 	metrics := c.App.Metrics
@@ -342,8 +536,10 @@ func searchPosts(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	results.PostList = c.App.PostListWithProxyAddedToImageURLs(postListWithMetadata(c, results.PostList))
+
 	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
-	w.Write([]byte(c.App.PostListWithProxyAddedToImageURLs(posts).ToJson()))
+	w.Write([]byte(results.ToJson()))
 }
 
 func updatePost(c *Context, w http.ResponseWriter, r *http.Request) {
@@ -371,7 +567,7 @@ func updatePost(c *Context, w http.ResponseWriter, r *http.Request) {
 
 	post.Id = c.Params.PostId
 
-	rpost, err := c.App.UpdatePost(c.App.PostWithProxyRemovedFromImageURLs(post), false)
+	rpost, err := c.App.UpdatePostByEditor(c.App.PostWithProxyRemovedFromImageURLs(post), false, c.Session.UserId)
 	if err != nil {
 		c.Err = err
 		return
@@ -403,7 +599,7 @@ func patchPost(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	patchedPost, err := c.App.PatchPost(c.Params.PostId, c.App.PostPatchWithProxyRemovedFromImageURLs(post))
+	patchedPost, err := c.App.PatchPostByEditor(c.Params.PostId, c.App.PostPatchWithProxyRemovedFromImageURLs(post), c.Session.UserId)
 	if err != nil {
 		c.Err = err
 		return
@@ -466,6 +662,31 @@ func getFileInfosForPost(c *Context, w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func getPostHistory(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequirePostId()
+	if c.Err != nil {
+		return
+	}
+
+	if *c.App.Config().ServiceSettings.RestrictPostEditHistoryToAdmins {
+		if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+			c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+			return
+		}
+	} else if !c.App.SessionHasPermissionToChannelByPost(c.Session, c.Params.PostId, model.PERMISSION_READ_CHANNEL) {
+		c.SetPermissionError(model.PERMISSION_READ_CHANNEL)
+		return
+	}
+
+	history, err := c.App.GetPostHistory(c.Params.PostId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(model.PostHistoryListToJson(history)))
+}
+
 func doPostAction(c *Context, w http.ResponseWriter, r *http.Request) {
 	c.RequirePostId().RequireActionId()
 	if c.Err != nil {
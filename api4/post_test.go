@@ -12,11 +12,16 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"github.com/mattermost/mattermost-server/app"
 	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
 )
 
 func TestCreatePost(t *testing.T) {
@@ -116,6 +121,88 @@ func TestCreatePost(t *testing.T) {
 	}
 }
 
+func TestCreatePostIdempotent(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+	Client := th.Client
+
+	post := &model.Post{ChannelId: th.BasicChannel.Id, Message: "retried " + model.NewId(), PendingPostId: th.BasicUser.Id + ":" + model.NewId()}
+
+	var wg sync.WaitGroup
+	responses := make([]*model.Response, 3)
+	posts := make([]*model.Post, 3)
+	for i := range responses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			posts[i], responses[i] = Client.CreatePost(post)
+		}(i)
+	}
+	wg.Wait()
+
+	created := 0
+	for i, resp := range responses {
+		CheckNoError(t, resp)
+		if resp.StatusCode == http.StatusCreated {
+			created++
+		} else {
+			CheckOKStatus(t, resp)
+		}
+
+		assert.Equal(t, posts[0].Id, posts[i].Id)
+		assert.Equal(t, post.PendingPostId, posts[i].PendingPostId)
+	}
+	assert.Equal(t, 1, created, "only one request should create the post")
+
+	rpost, resp := Client.CreatePost(post)
+	CheckNoError(t, resp)
+	CheckOKStatus(t, resp)
+	assert.Equal(t, posts[0].Id, rpost.Id)
+
+	list, resp := Client.GetPostsForChannel(th.BasicChannel.Id, 0, 100, "")
+	CheckNoError(t, resp)
+	count := 0
+	for _, p := range list.Posts {
+		if p.Message == post.Message {
+			count++
+		}
+	}
+	assert.Equal(t, 1, count)
+}
+
+func TestCreatePostWithChannelMentions(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	memberCount := th.App.Srv.Store.Channel().GetMemberCountFromCache(th.BasicChannel.Id)
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.TeamSettings.MaxNotificationsPerChannel = memberCount - 1 })
+
+	post := &model.Post{ChannelId: th.BasicChannel.Id, Message: "@channel announcement " + model.NewId()}
+	_, resp := Client.CreatePost(post)
+	CheckErrorMessage(t, resp, "api.post.check_channel_mentions_limit.app_error")
+	if resp.StatusCode != http.StatusPreconditionRequired {
+		t.Fatal("wrong status code")
+	}
+
+	rpost, resp := Client.CreatePostConfirmingChannelMentions(post)
+	CheckNoError(t, resp)
+	CheckCreatedStatus(t, resp)
+
+	if rpost.Message != post.Message {
+		t.Fatal("message didn't match")
+	}
+
+	// posts without channel wide mentions don't need to be confirmed
+	_, resp = Client.CreatePost(&model.Post{ChannelId: th.BasicChannel.Id, Message: "no mentions " + model.NewId()})
+	CheckNoError(t, resp)
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.TeamSettings.MaxNotificationsPerChannel = memberCount })
+
+	_, resp = Client.CreatePost(post)
+	CheckNoError(t, resp)
+}
+
 func TestCreatePostEphemeral(t *testing.T) {
 	th := Setup().InitBasic().InitSystemAdmin()
 	defer th.TearDown()
@@ -170,6 +257,15 @@ func testCreatePostWithOutgoingHook(
 	team := th.BasicTeam
 	channel := th.BasicChannel
 
+	// Posts can only have files that their author uploaded to their channel
+	data, err := readTestFile("test.png")
+	require.NoError(t, err)
+	for i := range fileIds {
+		fileResp, resp := th.SystemAdminClient.UploadFile(data, channel.Id, "test.png")
+		CheckNoError(t, resp)
+		fileIds[i] = fileResp.FileInfos[0].Id
+	}
+
 	th.App.UpdateConfig(func(cfg *model.Config) { cfg.ServiceSettings.EnableOutgoingWebhooks = true })
 	th.App.UpdateConfig(func(cfg *model.Config) {
 		*cfg.ServiceSettings.AllowedUntrustedInternalConnections = "localhost 127.0.0.1"
@@ -177,6 +273,7 @@ func testCreatePostWithOutgoingHook(
 
 	var hook *model.OutgoingWebhook
 	var post *model.Post
+	var requestId string
 
 	// Create a test server that is the target of the outgoing webhook. It will
 	// validate the webhook body fields and write to the success channel on
@@ -193,6 +290,12 @@ func testCreatePostWithOutgoingHook(
 			return
 		}
 
+		if r.Header.Get(model.HEADER_REQUEST_ID) != requestId {
+			t.Logf("X-Request-ID is %s, should be %s", r.Header.Get(model.HEADER_REQUEST_ID), requestId)
+			success <- false
+			return
+		}
+
 		expectedPayload := &model.OutgoingWebhookPayload{
 			Token:       hook.Token,
 			TeamId:      hook.TeamId,
@@ -281,6 +384,7 @@ func testCreatePostWithOutgoingHook(
 
 	post, resp = th.SystemAdminClient.CreatePost(post)
 	CheckNoError(t, resp)
+	requestId = resp.RequestId
 
 	wait <- true
 
@@ -851,6 +955,177 @@ func TestGetPostsForChannel(t *testing.T) {
 	CheckNoError(t, resp)
 }
 
+func TestGetPostsForChannelWithFields(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+	Client := th.Client
+
+	posts, resp := Client.GetPostsForChannelWithFields(th.BasicChannel.Id, 0, 60, []string{"id", "message"})
+	CheckNoError(t, resp)
+	require.NotEmpty(t, posts.Order)
+	post := posts.Posts[th.BasicPost.Id]
+	require.NotNil(t, post)
+	assert.Equal(t, th.BasicPost.Message, post.Message)
+	assert.Empty(t, post.UserId)
+	assert.Empty(t, post.ChannelId)
+
+	_, resp = Client.GetPostsForChannelWithFields(th.BasicChannel.Id, 0, 60, []string{"pending_post_id"})
+	CheckBadRequestStatus(t, resp)
+}
+
+func TestSetPostUnread(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	Client2 := th.CreateClient()
+	th.LoginBasic2WithClient(Client2)
+
+	first := th.CreateMessagePostWithClient(Client2, th.BasicChannel, "first")
+	th.CreateMessagePostWithClient(Client2, th.BasicChannel, "hi @"+th.BasicUser.Username)
+
+	_, resp := Client.ViewChannel(th.BasicUser.Id, &model.ChannelView{ChannelId: th.BasicChannel.Id})
+	CheckNoError(t, resp)
+
+	WebSocketClient, err := th.CreateWebSocketClient()
+	require.Nil(t, err)
+	WebSocketClient.Listen()
+	defer WebSocketClient.Close()
+
+	unread, resp := Client.SetPostUnread(th.BasicUser.Id, first.Id)
+	CheckNoError(t, resp)
+	assert.Equal(t, th.BasicChannel.Id, unread.ChannelId)
+	assert.Equal(t, int64(2), unread.MsgCount)
+	assert.Equal(t, int64(1), unread.MentionCount)
+	assert.Equal(t, first.CreateAt-1, unread.LastViewedAt)
+
+	timeout := time.After(2 * time.Second)
+	var event *model.WebSocketEvent
+	for event == nil {
+		select {
+		case e := <-WebSocketClient.EventChannel:
+			if e.Event == model.WEBSOCKET_EVENT_POST_UNREAD {
+				event = e
+			}
+		case <-timeout:
+			require.FailNow(t, "timed out waiting for the post unread event")
+		}
+	}
+	assert.Equal(t, th.BasicChannel.Id, event.Broadcast.ChannelId)
+	assert.Equal(t, first.Id, event.Data["post_id"])
+	assert.EqualValues(t, 2, event.Data["msg_count"])
+	assert.EqualValues(t, 1, event.Data["mention_count"])
+	assert.EqualValues(t, first.CreateAt-1, event.Data["last_viewed_at"])
+	assert.Equal(t, "", event.Data["last_read_post_id"])
+
+	channelUnread, resp := Client.GetChannelUnread(th.BasicChannel.Id, th.BasicUser.Id)
+	CheckNoError(t, resp)
+	assert.Equal(t, int64(2), channelUnread.MsgCount)
+	assert.Equal(t, int64(1), channelUnread.MentionCount)
+
+	_, resp = Client.SetPostUnread(th.BasicUser2.Id, first.Id)
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = Client.SetPostUnread(th.BasicUser.Id, "junk")
+	CheckBadRequestStatus(t, resp)
+
+	private := th.CreatePrivateChannel()
+	_, resp = th.SystemAdminClient.SetPostUnread(th.BasicUser.Id, th.CreatePostWithClient(Client, private).Id)
+	CheckNoError(t, resp)
+
+	_, resp = Client2.SetPostUnread(th.BasicUser2.Id, th.CreatePostWithClient(Client, private).Id)
+	CheckForbiddenStatus(t, resp)
+
+	Client.Logout()
+	_, resp = Client.SetPostUnread(th.BasicUser.Id, first.Id)
+	CheckUnauthorizedStatus(t, resp)
+}
+
+func TestSetPostRead(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	// a second session for the same user, as if on another device
+	OtherDevice := th.CreateClient()
+	th.LoginBasicWithClient(OtherDevice)
+
+	Client2 := th.CreateClient()
+	th.LoginBasic2WithClient(Client2)
+
+	first := th.CreateMessagePostWithClient(Client2, th.BasicChannel, "first")
+	second := th.CreateMessagePostWithClient(Client2, th.BasicChannel, "second")
+	third := th.CreateMessagePostWithClient(Client2, th.BasicChannel, "third")
+
+	WebSocketClient, err := th.CreateWebSocketClient()
+	require.Nil(t, err)
+	WebSocketClient.Listen()
+	defer WebSocketClient.Close()
+
+	unread, resp := Client.SetPostRead(th.BasicUser.Id, first.Id)
+	CheckNoError(t, resp)
+	assert.Equal(t, th.BasicChannel.Id, unread.ChannelId)
+	assert.Equal(t, first.Id, unread.LastReadPostId)
+
+	unread, resp = OtherDevice.SetPostRead(th.BasicUser.Id, third.Id)
+	CheckNoError(t, resp)
+	assert.Equal(t, third.Id, unread.LastReadPostId)
+
+	// the first device catching up to a post that the other has already read past leaves the line where it is
+	unread, resp = Client.SetPostRead(th.BasicUser.Id, second.Id)
+	CheckNoError(t, resp)
+	assert.Equal(t, third.Id, unread.LastReadPostId)
+
+	timeout := time.After(2 * time.Second)
+	var lastReadPostIds []string
+	for len(lastReadPostIds) < 2 {
+		select {
+		case e := <-WebSocketClient.EventChannel:
+			if e.Event == model.WEBSOCKET_EVENT_POST_READ {
+				assert.Equal(t, th.BasicChannel.Id, e.Broadcast.ChannelId)
+				lastReadPostIds = append(lastReadPostIds, e.Data["last_read_post_id"].(string))
+			}
+		case <-timeout:
+			require.FailNow(t, "timed out waiting for the post read events")
+		}
+	}
+	assert.Equal(t, []string{first.Id, third.Id}, lastReadPostIds)
+
+	channelUnread, resp := OtherDevice.GetChannelUnread(th.BasicChannel.Id, th.BasicUser.Id)
+	CheckNoError(t, resp)
+	assert.Equal(t, third.Id, channelUnread.LastReadPostId)
+
+	channelUnreads, resp := Client.GetChannelUnreadsForTeamForUser(th.BasicTeam.Id, th.BasicUser.Id)
+	CheckNoError(t, resp)
+	for _, channelUnread := range channelUnreads {
+		if channelUnread.ChannelId == th.BasicChannel.Id {
+			assert.Equal(t, third.Id, channelUnread.LastReadPostId)
+		}
+	}
+
+	// marking a post as unread on one device moves the line back for both
+	_, resp = OtherDevice.SetPostUnread(th.BasicUser.Id, second.Id)
+	CheckNoError(t, resp)
+
+	channelUnread, resp = Client.GetChannelUnread(th.BasicChannel.Id, th.BasicUser.Id)
+	CheckNoError(t, resp)
+	assert.Equal(t, first.Id, channelUnread.LastReadPostId)
+
+	_, resp = Client.SetPostRead(th.BasicUser2.Id, first.Id)
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = Client.SetPostRead(th.BasicUser.Id, "junk")
+	CheckBadRequestStatus(t, resp)
+
+	private := th.CreatePrivateChannel()
+	_, resp = Client2.SetPostRead(th.BasicUser2.Id, th.CreatePostWithClient(Client, private).Id)
+	CheckForbiddenStatus(t, resp)
+
+	Client.Logout()
+	_, resp = Client.SetPostRead(th.BasicUser.Id, first.Id)
+	CheckUnauthorizedStatus(t, resp)
+}
+
 func TestGetFlaggedPostsForUser(t *testing.T) {
 	th := Setup().InitBasic().InitSystemAdmin()
 	defer th.TearDown()
@@ -1165,6 +1440,152 @@ func TestGetPost(t *testing.T) {
 	CheckNoError(t, resp)
 }
 
+func TestGetPostPermalinkPreviews(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.SiteURL = "http://localhost:8065" })
+
+	private := th.CreatePrivateChannel()
+	privatePost := th.CreatePostWithClient(Client, private)
+
+	post := th.CreateMessagePost("http://localhost:8065/" + th.BasicTeam.Name + "/pl/" + privatePost.Id)
+
+	rpost, resp := Client.GetPost(post.Id, "")
+	CheckNoError(t, resp)
+	require.NotNil(t, rpost.Metadata)
+	require.Len(t, rpost.Metadata.Embeds, 1)
+	assert.Equal(t, model.POST_EMBED_PERMALINK, rpost.Metadata.Embeds[0].Type)
+	assert.Equal(t, privatePost.Id, rpost.Metadata.Embeds[0].Data.(map[string]interface{})["post_id"])
+	assert.Equal(t, privatePost.Message, rpost.Metadata.Embeds[0].Data.(map[string]interface{})["message"])
+
+	th.LoginBasic2()
+
+	rpost, resp = Client.GetPost(post.Id, "")
+	CheckNoError(t, resp)
+	assert.Nil(t, rpost.Metadata)
+
+	list, resp := Client.GetPostsForChannel(th.BasicChannel.Id, 0, 60, "")
+	CheckNoError(t, resp)
+	assert.Nil(t, list.Posts[post.Id].Metadata)
+
+	list, resp = th.SystemAdminClient.GetPostsForChannel(th.BasicChannel.Id, 0, 60, "")
+	CheckNoError(t, resp)
+	require.NotNil(t, list.Posts[post.Id].Metadata)
+	assert.Len(t, list.Posts[post.Id].Metadata.Embeds, 1)
+}
+
+func TestGetPostsForChannelWithMetadata(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+	Client := th.Client
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableCustomEmoji = true })
+
+	emoji, resp := Client.CreateEmoji(&model.Emoji{CreatorId: th.BasicUser.Id, Name: "e" + model.NewId()[:10]}, utils.CreateTestGif(t, 10, 10), "image.gif")
+	CheckNoError(t, resp)
+
+	fileResp, resp := Client.UploadFile([]byte("data"), th.BasicChannel.Id, "test.txt")
+	CheckNoError(t, resp)
+	fileId := fileResp.FileInfos[0].Id
+
+	plain := th.CreateMessagePost("plain")
+	withFile, resp := Client.CreatePost(&model.Post{ChannelId: th.BasicChannel.Id, Message: "with a file", FileIds: []string{fileId}})
+	CheckNoError(t, resp)
+	withEmoji := th.CreateMessagePost("using :" + emoji.Name + ":")
+	withImage := th.CreateMessagePost("https://www.example.com/cat.png")
+
+	_, resp = Client.SaveReaction(&model.Reaction{UserId: th.BasicUser.Id, PostId: plain.Id, EmojiName: "smile"})
+	CheckNoError(t, resp)
+
+	t.Run("not requested", func(t *testing.T) {
+		list, resp := Client.GetPostsForChannel(th.BasicChannel.Id, 0, 60, "")
+		CheckNoError(t, resp)
+
+		require.NotNil(t, list.Posts[plain.Id].Metadata)
+		assert.Len(t, list.Posts[plain.Id].Metadata.Reactions, 1)
+		assert.Nil(t, list.Posts[withFile.Id].Metadata)
+		assert.Nil(t, list.Posts[withEmoji.Id].Metadata)
+		assert.Nil(t, list.Posts[withImage.Id].Metadata)
+	})
+
+	t.Run("requested", func(t *testing.T) {
+		list, resp := Client.GetPostsForChannelWithMetadata(th.BasicChannel.Id, 0, 60, "")
+		CheckNoError(t, resp)
+
+		require.NotNil(t, list.Posts[plain.Id].Metadata)
+		assert.Equal(t, &model.ReactionSummary{Count: 1, Me: true}, list.Posts[plain.Id].Metadata.Reactions["smile"])
+		assert.Empty(t, list.Posts[plain.Id].Metadata.Emojis)
+		assert.Empty(t, list.Posts[plain.Id].Metadata.Files)
+
+		require.NotNil(t, list.Posts[withFile.Id].Metadata)
+		require.Len(t, list.Posts[withFile.Id].Metadata.Files, 1)
+		assert.Equal(t, fileId, list.Posts[withFile.Id].Metadata.Files[0].Id)
+
+		require.NotNil(t, list.Posts[withEmoji.Id].Metadata)
+		require.Len(t, list.Posts[withEmoji.Id].Metadata.Emojis, 1)
+		assert.Equal(t, emoji.Id, list.Posts[withEmoji.Id].Metadata.Emojis[0].Id)
+
+		require.NotNil(t, list.Posts[withImage.Id].Metadata)
+		require.Len(t, list.Posts[withImage.Id].Metadata.Embeds, 1)
+		assert.Equal(t, model.POST_EMBED_IMAGE, list.Posts[withImage.Id].Metadata.Embeds[0].Type)
+	})
+}
+
+func TestGetSystemPostInUsersLocale(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+	Client := th.Client
+
+	th.BasicUser2.Locale = "de"
+	_, err := th.App.UpdateUser(th.BasicUser2, false)
+	require.Nil(t, err)
+
+	require.Nil(t, th.App.PostUpdateChannelDisplayNameMessage(th.BasicUser.Id, th.BasicChannel, "Old", "New"))
+
+	list, resp := Client.GetPostsForChannel(th.BasicChannel.Id, 0, 60, "")
+	CheckNoError(t, resp)
+
+	var post *model.Post
+	for _, p := range list.Posts {
+		if p.Type == model.POST_DISPLAYNAME_CHANGE {
+			post = p
+		}
+	}
+	require.NotNil(t, post)
+
+	english := fmt.Sprintf(utils.GetUserTranslations("en")("api.channel.post_update_channel_displayname_message_and_forget.updated_from"), th.BasicUser.Username, "Old", "New")
+	german := fmt.Sprintf(utils.GetUserTranslations("de")("api.channel.post_update_channel_displayname_message_and_forget.updated_from"), th.BasicUser.Username, "Old", "New")
+	require.NotEqual(t, english, german)
+	assert.Equal(t, english, post.Message)
+
+	th.LoginBasic2()
+
+	rpost, resp := Client.GetPost(post.Id, "")
+	CheckNoError(t, resp)
+	assert.Equal(t, german, rpost.Message)
+
+	list, resp = Client.GetPostsForChannel(th.BasicChannel.Id, 0, 60, "")
+	CheckNoError(t, resp)
+	assert.Equal(t, german, list.Posts[post.Id].Message)
+
+	t.Run("old posts keep their stored text", func(t *testing.T) {
+		oldPost, err := th.App.CreatePost(&model.Post{
+			ChannelId: th.BasicChannel.Id,
+			UserId:    th.BasicUser.Id,
+			Type:      model.POST_CHANGE_CHANNEL_PRIVACY,
+			Message:   "stored text",
+			Props:     model.StringInterface{"username": th.BasicUser.Username},
+		}, th.BasicChannel, false)
+		require.Nil(t, err)
+
+		rpost, resp := Client.GetPost(oldPost.Id, "")
+		CheckNoError(t, resp)
+		assert.Equal(t, "stored text", rpost.Message)
+	})
+}
+
 func TestDeletePost(t *testing.T) {
 	th := Setup().InitBasic().InitSystemAdmin()
 	defer th.TearDown()
@@ -1203,6 +1624,139 @@ func TestDeletePost(t *testing.T) {
 	CheckNoError(t, resp)
 }
 
+func TestDeletePostTimeLimit(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	th.App.SetLicense(model.NewTestLicense())
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.ServiceSettings.PostDeleteTimeLimit = 300
+		cfg.ServiceSettings.PostDeleteTimeLimitByRole = map[string]int{model.SYSTEM_ADMIN_ROLE_ID: -1}
+	})
+	defer th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.ServiceSettings.PostDeleteTimeLimit = -1
+		cfg.ServiceSettings.PostDeleteTimeLimitByRole = map[string]int{}
+	})
+
+	oldPost, err := th.App.CreatePost(&model.Post{
+		ChannelId: th.BasicChannel.Id,
+		UserId:    th.BasicUser.Id,
+		Message:   "old",
+		CreateAt:  model.GetMillis() - 600*1000,
+	}, th.BasicChannel, false)
+	require.Nil(t, err)
+
+	_, resp := Client.DeletePost(oldPost.Id)
+	CheckBadRequestStatus(t, resp)
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, "api.post.delete_post.permissions_time_limit.app_error", resp.Error.Id)
+
+	newPost := th.CreatePost()
+	_, resp = Client.DeletePost(newPost.Id)
+	CheckNoError(t, resp)
+
+	_, resp = th.SystemAdminClient.DeletePost(oldPost.Id)
+	CheckNoError(t, resp)
+
+	config, resp := Client.GetOldClientConfig("")
+	CheckNoError(t, resp)
+	assert.Equal(t, "300", config["PostDeleteTimeLimit"])
+	assert.Equal(t, `{"system_admin":-1}`, config["PostDeleteTimeLimitByRole"])
+	assert.Equal(t, "{}", config["PostEditTimeLimitByRole"])
+}
+
+func TestForwardPost(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	privatePost := th.CreatePostWithClient(Client, th.BasicPrivateChannel)
+
+	post, resp := Client.ForwardPost(privatePost.Id, &model.PostForward{ChannelId: th.BasicChannel.Id, Message: "fyi"})
+	CheckNoError(t, resp)
+	CheckCreatedStatus(t, resp)
+
+	assert.Equal(t, th.BasicChannel.Id, post.ChannelId)
+	assert.Equal(t, th.BasicUser.Id, post.UserId)
+	assert.Equal(t, "fyi", post.Message)
+
+	preview := post.Props[model.POST_PROPS_FORWARDED_POST].(map[string]interface{})
+	assert.Equal(t, privatePost.Id, preview["post_id"])
+	assert.Equal(t, th.BasicUser.Username, preview["username"])
+	assert.Equal(t, th.BasicPrivateChannel.Id, preview["channel_id"])
+	assert.Equal(t, privatePost.Message, preview["excerpt"])
+
+	t.Run("degraded when the channel can't read the original", func(t *testing.T) {
+		user := th.CreateUser()
+		th.LinkUserToTeam(user, th.BasicTeam)
+		th.AddUserToChannel(user, th.BasicChannel)
+
+		post, resp := Client.ForwardPost(privatePost.Id, &model.PostForward{ChannelId: th.BasicChannel.Id})
+		CheckNoError(t, resp)
+
+		preview := post.Props[model.POST_PROPS_FORWARDED_POST].(map[string]interface{})
+		assert.Len(t, preview, 2)
+		assert.Equal(t, privatePost.Id, preview["post_id"])
+		assert.Equal(t, preview["permalink"], post.Message)
+		assert.Contains(t, post.Message, "/"+th.BasicTeam.Name+"/pl/"+privatePost.Id)
+	})
+
+	t.Run("clients can't set the preview", func(t *testing.T) {
+		fake := map[string]interface{}{"post_id": privatePost.Id, "excerpt": "made up"}
+
+		created, resp := Client.CreatePost(&model.Post{
+			ChannelId: th.BasicChannel.Id,
+			Message:   "not forwarded",
+			Props:     model.StringInterface{model.POST_PROPS_FORWARDED_POST: fake},
+		})
+		CheckNoError(t, resp)
+		assert.Nil(t, created.Props[model.POST_PROPS_FORWARDED_POST])
+
+		created.Props = model.StringInterface{model.POST_PROPS_FORWARDED_POST: fake}
+		updated, resp := Client.UpdatePost(created.Id, created)
+		CheckNoError(t, resp)
+		assert.Nil(t, updated.Props[model.POST_PROPS_FORWARDED_POST])
+
+		post.Message = "edited"
+		post.Props = model.StringInterface{model.POST_PROPS_FORWARDED_POST: fake}
+		updated, resp = Client.UpdatePost(post.Id, post)
+		CheckNoError(t, resp)
+		assert.Equal(t, preview, updated.Props[model.POST_PROPS_FORWARDED_POST], "editing a forwarded post keeps its preview")
+	})
+
+	t.Run("invalid target", func(t *testing.T) {
+		_, resp := Client.ForwardPost(privatePost.Id, &model.PostForward{ChannelId: "junk"})
+		CheckBadRequestStatus(t, resp)
+	})
+
+	t.Run("can't post in the target", func(t *testing.T) {
+		other, err := th.App.CreateChannel(&model.Channel{
+			DisplayName: "other",
+			Name:        "other-" + model.NewId(),
+			Type:        model.CHANNEL_PRIVATE,
+			TeamId:      th.BasicTeam.Id,
+			CreatorId:   th.BasicUser2.Id,
+		}, true)
+		require.Nil(t, err)
+
+		_, resp := Client.ForwardPost(privatePost.Id, &model.PostForward{ChannelId: other.Id})
+		CheckForbiddenStatus(t, resp)
+
+		// nor read the original
+		otherPost, err := th.App.CreatePost(&model.Post{ChannelId: other.Id, UserId: th.BasicUser2.Id, Message: "secret"}, other, false)
+		require.Nil(t, err)
+
+		_, resp = Client.ForwardPost(otherPost.Id, &model.PostForward{ChannelId: th.BasicChannel.Id})
+		CheckForbiddenStatus(t, resp)
+	})
+
+	Client.Logout()
+	_, resp = Client.ForwardPost(privatePost.Id, &model.PostForward{ChannelId: th.BasicChannel.Id})
+	CheckUnauthorizedStatus(t, resp)
+}
+
 func TestGetPostThread(t *testing.T) {
 	th := Setup().InitBasic().InitSystemAdmin()
 	defer th.TearDown()
@@ -1322,6 +1876,71 @@ func TestSearchPosts(t *testing.T) {
 
 }
 
+func TestSearchPostsPaging(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+	Client := th.Client
+
+	createPost := func(createAt int64) *model.Post {
+		post, err := th.App.CreatePost(&model.Post{
+			ChannelId: th.BasicChannel.Id,
+			UserId:    th.BasicUser.Id,
+			Message:   "pagingterm " + model.NewId(),
+			CreateAt:  createAt,
+		}, th.BasicChannel, false)
+		require.Nil(t, err)
+		return post
+	}
+
+	base := model.GetMillis() - 60*1000
+	var expected []string
+	for i := 0; i < 7; i++ {
+		post := createPost(base + int64(i)*1000)
+		expected = append([]string{post.Id}, expected...)
+	}
+
+	first, resp := Client.SearchPostsWithPaging(th.BasicTeam.Id, "pagingterm", false, 0, 0, 3)
+	CheckNoError(t, resp)
+	require.Len(t, first.Order, 3)
+	assert.Equal(t, int64(7), first.TotalCount)
+	assert.False(t, first.IsApproximate)
+	require.NotZero(t, first.SearchAt)
+
+	// new posts made between getting pages don't shift the following pages
+	createPost(first.SearchAt + 1)
+	createPost(first.SearchAt + 2)
+
+	second, resp := Client.SearchPostsWithPaging(th.BasicTeam.Id, "pagingterm", false, first.SearchAt, 1, 3)
+	CheckNoError(t, resp)
+	assert.Equal(t, first.SearchAt, second.SearchAt)
+	assert.Equal(t, int64(7), second.TotalCount)
+
+	createPost(first.SearchAt + 3)
+
+	third, resp := Client.SearchPostsWithPaging(th.BasicTeam.Id, "pagingterm", false, first.SearchAt, 2, 3)
+	CheckNoError(t, resp)
+
+	var got []string
+	got = append(got, first.Order...)
+	got = append(got, second.Order...)
+	got = append(got, third.Order...)
+	assert.Equal(t, expected, got)
+
+	beyond, resp := Client.SearchPostsWithPaging(th.BasicTeam.Id, "pagingterm", false, first.SearchAt, 3, 3)
+	CheckNoError(t, resp)
+	assert.Len(t, beyond.Order, 0)
+
+	// a new search includes the new posts
+	fresh, resp := Client.SearchPostsWithPaging(th.BasicTeam.Id, "pagingterm", false, 0, 0, 3)
+	CheckNoError(t, resp)
+	assert.Equal(t, int64(10), fresh.TotalCount)
+	assert.True(t, fresh.SearchAt >= first.SearchAt)
+
+	_, appErr := Client.DoApiPost(Client.GetTeamRoute(th.BasicTeam.Id)+"/posts/search", `{"terms": "pagingterm", "page": -1}`)
+	require.NotNil(t, appErr)
+	assert.Equal(t, http.StatusBadRequest, appErr.StatusCode)
+}
+
 func TestSearchHashtagPosts(t *testing.T) {
 	th := Setup().InitBasic()
 	defer th.TearDown()
@@ -1478,6 +2097,58 @@ func TestSearchPostsFromUser(t *testing.T) {
 	}
 }
 
+func TestGetPostHistory(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	post, resp := Client.CreatePost(&model.Post{ChannelId: th.BasicChannel.Id, Message: "first"})
+	CheckNoError(t, resp)
+
+	_, resp = Client.PatchPost(post.Id, &model.PostPatch{Message: model.NewString("second")})
+	CheckNoError(t, resp)
+
+	_, resp = th.SystemAdminClient.PatchPost(post.Id, &model.PostPatch{Message: model.NewString("third")})
+	CheckNoError(t, resp)
+
+	history, resp := Client.GetPostHistory(post.Id)
+	CheckNoError(t, resp)
+	require.Len(t, history, 2)
+	assert.Equal(t, "first", history[0].Message)
+	assert.Equal(t, th.BasicUser.Id, history[0].EditorId)
+	assert.Equal(t, "second", history[1].Message)
+	assert.Equal(t, th.SystemAdminUser.Id, history[1].EditorId)
+	assert.True(t, history[0].EditAt <= history[1].EditAt)
+
+	_, resp = Client.GetPostHistory("junk")
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = Client.GetPostHistory(model.NewId())
+	CheckForbiddenStatus(t, resp)
+
+	// only channel members can see what a post used to say
+	privateChannel := th.CreatePrivateChannel()
+	privatePost := th.CreatePostWithClient(Client, privateChannel)
+
+	th.LoginBasic2()
+	_, resp = Client.GetPostHistory(privatePost.Id)
+	CheckForbiddenStatus(t, resp)
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.RestrictPostEditHistoryToAdmins = true })
+
+	th.LoginBasic()
+	_, resp = Client.GetPostHistory(post.Id)
+	CheckForbiddenStatus(t, resp)
+
+	history, resp = th.SystemAdminClient.GetPostHistory(post.Id)
+	CheckNoError(t, resp)
+	assert.Len(t, history, 2)
+
+	Client.Logout()
+	_, resp = Client.GetPostHistory(post.Id)
+	CheckUnauthorizedStatus(t, resp)
+}
+
 func TestGetFileInfosForPost(t *testing.T) {
 	th := Setup().InitBasic().InitSystemAdmin()
 	defer th.TearDown()
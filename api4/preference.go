@@ -28,6 +28,17 @@ func getPreferences(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// all of the user's preferences are returned unless the request asks for a category or a page of them
+	query := r.URL.Query()
+	if category := query.Get("category"); category != "" || query.Get("page") != "" || query.Get("per_page") != "" {
+		if preferences, err := c.App.GetPreferencesPageForUser(c.Params.UserId, category, c.Params.Page, c.Params.PerPage); err != nil {
+			c.Err = err
+		} else {
+			w.Write([]byte(preferences.ToJson()))
+		}
+		return
+	}
+
 	if preferences, err := c.App.GetPreferencesForUser(c.Params.UserId); err != nil {
 		c.Err = err
 		return
@@ -8,6 +8,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/app"
 	"github.com/mattermost/mattermost-server/model"
 )
 
@@ -19,7 +23,7 @@ func TestGetPreferences(t *testing.T) {
 	th.LoginBasic()
 	user1 := th.BasicUser
 
-	category := model.NewId()
+	category := model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL
 	preferences1 := model.Preferences{
 		{
 			UserId:   user1.Id,
@@ -33,7 +37,7 @@ func TestGetPreferences(t *testing.T) {
 		},
 		{
 			UserId:   user1.Id,
-			Category: model.NewId(),
+			Category: model.PREFERENCE_CATEGORY_FLAGGED_POST,
 			Name:     model.NewId(),
 		},
 	}
@@ -77,7 +81,7 @@ func TestGetPreferencesByCategory(t *testing.T) {
 	th.LoginBasic()
 	user1 := th.BasicUser
 
-	category := model.NewId()
+	category := model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL
 	preferences1 := model.Preferences{
 		{
 			UserId:   user1.Id,
@@ -91,7 +95,7 @@ func TestGetPreferencesByCategory(t *testing.T) {
 		},
 		{
 			UserId:   user1.Id,
-			Category: model.NewId(),
+			Category: model.PREFERENCE_CATEGORY_FLAGGED_POST,
 			Name:     model.NewId(),
 		},
 	}
@@ -191,7 +195,7 @@ func TestUpdatePreferences(t *testing.T) {
 	th.LoginBasic()
 	user1 := th.BasicUser
 
-	category := model.NewId()
+	category := model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL
 	preferences1 := model.Preferences{
 		{
 			UserId:   user1.Id,
@@ -205,7 +209,7 @@ func TestUpdatePreferences(t *testing.T) {
 		},
 		{
 			UserId:   user1.Id,
-			Category: model.NewId(),
+			Category: model.PREFERENCE_CATEGORY_FLAGGED_POST,
 			Name:     model.NewId(),
 		},
 	}
@@ -242,6 +246,107 @@ func TestUpdatePreferences(t *testing.T) {
 	CheckUnauthorizedStatus(t, resp)
 }
 
+func TestUpdatePreferencesValidation(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+	Client := th.Client
+
+	user := th.BasicUser
+
+	t.Run("unknown category", func(t *testing.T) {
+		preferences := model.Preferences{{UserId: user.Id, Category: model.NewId(), Name: model.NewId()}}
+		_, resp := Client.UpdatePreferences(user.Id, &preferences)
+		CheckBadRequestStatus(t, resp)
+		CheckErrorMessage(t, resp, "model.preference.is_valid.unknown_category.app_error")
+	})
+
+	t.Run("name that isn't an id", func(t *testing.T) {
+		preferences := model.Preferences{{UserId: user.Id, Category: model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, Name: "junk"}}
+		_, resp := Client.UpdatePreferences(user.Id, &preferences)
+		CheckBadRequestStatus(t, resp)
+	})
+
+	t.Run("nothing is saved if any preference is invalid", func(t *testing.T) {
+		valid := model.Preference{UserId: user.Id, Category: model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, Name: model.NewId(), Value: "true"}
+		preferences := model.Preferences{
+			valid,
+			{UserId: user.Id, Category: model.NewId(), Name: model.NewId()},
+		}
+		_, resp := Client.UpdatePreferences(user.Id, &preferences)
+		CheckBadRequestStatus(t, resp)
+
+		_, resp = Client.GetPreferenceByCategoryAndName(user.Id, valid.Category, valid.Name)
+		CheckBadRequestStatus(t, resp)
+
+		preferences = model.Preferences{
+			valid,
+			{UserId: user.Id, Category: model.PREFERENCE_CATEGORY_THEME, Value: "not a theme"},
+		}
+		_, resp = Client.UpdatePreferences(user.Id, &preferences)
+		CheckBadRequestStatus(t, resp)
+
+		_, resp = Client.GetPreferenceByCategoryAndName(user.Id, valid.Category, valid.Name)
+		CheckBadRequestStatus(t, resp)
+	})
+
+	t.Run("too many preferences", func(t *testing.T) {
+		var preferences model.Preferences
+		for i := 0; i < model.PREFERENCES_MAX_UPDATE; i++ {
+			preferences = append(preferences, model.Preference{UserId: user.Id, Category: model.PREFERENCE_CATEGORY_FLAGGED_POST, Name: model.NewId(), Value: "true"})
+		}
+
+		_, resp := Client.UpdatePreferences(user.Id, &preferences)
+		CheckNoError(t, resp)
+
+		prefs, resp := Client.GetPreferencesByCategory(user.Id, model.PREFERENCE_CATEGORY_FLAGGED_POST)
+		CheckNoError(t, resp)
+		assert.Len(t, prefs, model.PREFERENCES_MAX_UPDATE)
+
+		preferences = append(preferences, model.Preference{UserId: user.Id, Category: model.PREFERENCE_CATEGORY_FLAGGED_POST, Name: model.NewId(), Value: "true"})
+		_, resp = Client.UpdatePreferences(user.Id, &preferences)
+		CheckBadRequestStatus(t, resp)
+		CheckErrorMessage(t, resp, "api.preference.update_preferences.too_many.app_error")
+	})
+}
+
+func TestGetPreferencesPage(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+	Client := th.Client
+
+	user := th.BasicUser
+
+	preferences := model.Preferences{
+		{UserId: user.Id, Category: model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, Name: model.NewId(), Value: "true"},
+		{UserId: user.Id, Category: model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, Name: model.NewId(), Value: "true"},
+		{UserId: user.Id, Category: model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, Name: model.NewId(), Value: "true"},
+	}
+	_, resp := Client.UpdatePreferences(user.Id, &preferences)
+	CheckNoError(t, resp)
+
+	all, resp := Client.GetPreferences(user.Id)
+	CheckNoError(t, resp)
+
+	prefs, resp := Client.GetPreferencesPage(user.Id, "", 0, 100)
+	CheckNoError(t, resp)
+	assert.Len(t, prefs, len(all))
+
+	prefs, resp = Client.GetPreferencesPage(user.Id, model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, 0, 2)
+	CheckNoError(t, resp)
+	require.Len(t, prefs, 2)
+	for _, preference := range prefs {
+		assert.Equal(t, model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, preference.Category)
+	}
+
+	next, resp := Client.GetPreferencesPage(user.Id, model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, 1, 2)
+	CheckNoError(t, resp)
+	require.Len(t, next, 1)
+	assert.NotContains(t, prefs, next[0])
+
+	_, resp = Client.GetPreferencesPage(th.BasicUser2.Id, "", 0, 100)
+	CheckForbiddenStatus(t, resp)
+}
+
 func TestUpdatePreferencesWebsocket(t *testing.T) {
 	th := Setup().InitBasic()
 	defer th.TearDown()
@@ -261,12 +366,12 @@ func TestUpdatePreferencesWebsocket(t *testing.T) {
 	preferences := &model.Preferences{
 		{
 			UserId:   userId,
-			Category: model.NewId(),
+			Category: model.PREFERENCE_CATEGORY_FLAGGED_POST,
 			Name:     model.NewId(),
 		},
 		{
 			UserId:   userId,
-			Category: model.NewId(),
+			Category: model.PREFERENCE_CATEGORY_FLAGGED_POST,
 			Name:     model.NewId(),
 		},
 	}
@@ -302,6 +407,82 @@ func TestUpdatePreferencesWebsocket(t *testing.T) {
 	}
 }
 
+func TestUpdatePreferencesWebsocketBatched(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	WebSocketClient, err := th.CreateWebSocketClient()
+	require.Nil(t, err)
+
+	WebSocketClient.Listen()
+	time.Sleep(300 * time.Millisecond)
+	if resp := <-WebSocketClient.ResponseChannel; resp.Status != model.STATUS_OK {
+		t.Fatal("should have responded OK to authentication challenge")
+	}
+
+	newPreferences := func(value string) model.Preferences {
+		var preferences model.Preferences
+		for i := 0; i < 40; i++ {
+			preferences = append(preferences, model.Preference{
+				UserId:   th.BasicUser.Id,
+				Category: model.PREFERENCE_CATEGORY_FLAGGED_POST,
+				Name:     model.NewId(),
+				Value:    value,
+			})
+		}
+		return preferences
+	}
+
+	receiveEvents := func(t *testing.T, eventType string) []model.Preferences {
+		var received []model.Preferences
+
+		timeout := time.After(time.Second)
+		for {
+			select {
+			case event := <-WebSocketClient.EventChannel:
+				if event.Event != eventType {
+					continue
+				}
+
+				data := event.Data["preferences"].(string)
+				assert.True(t, len(data) <= app.PREFERENCES_EVENT_MAX_SIZE)
+				preferences, err := model.PreferencesFromJson(strings.NewReader(data))
+				require.Nil(t, err)
+				received = append(received, preferences)
+			case <-timeout:
+				return received
+			}
+		}
+	}
+
+	t.Run("one event", func(t *testing.T) {
+		preferences := newPreferences("true")
+		_, resp := th.Client.UpdatePreferences(th.BasicUser.Id, &preferences)
+		CheckNoError(t, resp)
+
+		received := receiveEvents(t, model.WEBSOCKET_EVENT_PREFERENCES_CHANGED)
+		require.Len(t, received, 1, "saving the preferences together should send one event")
+		assert.Equal(t, preferences, received[0])
+
+		_, resp = th.Client.DeletePreferences(th.BasicUser.Id, &preferences)
+		CheckNoError(t, resp)
+
+		received = receiveEvents(t, model.WEBSOCKET_EVENT_PREFERENCES_DELETED)
+		require.Len(t, received, 1, "deleting the preferences together should send one event")
+		assert.Equal(t, preferences, received[0])
+	})
+
+	t.Run("split at the size cap", func(t *testing.T) {
+		preferences := newPreferences(strings.Repeat("a", 2000))
+		_, resp := th.Client.UpdatePreferences(th.BasicUser.Id, &preferences)
+		CheckNoError(t, resp)
+
+		received := receiveEvents(t, model.WEBSOCKET_EVENT_PREFERENCES_CHANGED)
+		assert.Equal(t, preferences.SplitByJsonSize(app.PREFERENCES_EVENT_MAX_SIZE), received)
+		assert.True(t, len(received) > 1)
+	})
+}
+
 func TestDeletePreferences(t *testing.T) {
 	th := Setup().InitBasic()
 	defer th.TearDown()
@@ -357,12 +538,12 @@ func TestDeletePreferencesWebsocket(t *testing.T) {
 	preferences := &model.Preferences{
 		{
 			UserId:   userId,
-			Category: model.NewId(),
+			Category: model.PREFERENCE_CATEGORY_FLAGGED_POST,
 			Name:     model.NewId(),
 		},
 		{
 			UserId:   userId,
-			Category: model.NewId(),
+			Category: model.PREFERENCE_CATEGORY_FLAGGED_POST,
 			Name:     model.NewId(),
 		},
 	}
@@ -0,0 +1,97 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func (api *API) InitProvisioningToken() {
+	api.BaseRoutes.ProvisioningTokens.Handle("", api.ApiSessionRequired(createProvisioningToken)).Methods("POST")
+	api.BaseRoutes.ProvisioningTokens.Handle("", api.ApiSessionRequired(getProvisioningTokens)).Methods("GET")
+	api.BaseRoutes.ProvisioningToken.Handle("", api.ApiSessionRequired(getProvisioningToken)).Methods("GET")
+	api.BaseRoutes.ProvisioningToken.Handle("", api.ApiSessionRequired(revokeProvisioningToken)).Methods("DELETE")
+}
+
+func createProvisioningToken(c *Context, w http.ResponseWriter, r *http.Request) {
+	token := model.ProvisioningTokenFromJson(r.Body)
+	if token == nil {
+		c.SetInvalidParam("provisioning_token")
+		return
+	}
+
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	token.CreatorId = c.Session.UserId
+
+	created, err := c.App.CreateProvisioningToken(token)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("token_id=" + created.Id)
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(created.ToJson()))
+}
+
+func getProvisioningTokens(c *Context, w http.ResponseWriter, r *http.Request) {
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	tokens, err := c.App.GetProvisioningTokens(c.Params.Page, c.Params.PerPage)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(model.ProvisioningTokenListToJson(tokens)))
+}
+
+func getProvisioningToken(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireTokenId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	token, err := c.App.GetProvisioningToken(c.Params.TokenId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(token.ToJson()))
+}
+
+func revokeProvisioningToken(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireTokenId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	if err := c.App.RevokeProvisioningToken(c.Params.TokenId); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("token_id=" + c.Params.TokenId)
+	ReturnStatusOK(w)
+}
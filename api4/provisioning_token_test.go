@@ -0,0 +1,49 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvisioningTokens(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+
+	_, resp := th.Client.CreateProvisioningToken("okta")
+	CheckForbiddenStatus(t, resp)
+
+	token, resp := th.SystemAdminClient.CreateProvisioningToken("okta")
+	CheckNoError(t, resp)
+	CheckCreatedStatus(t, resp)
+	assert.Equal(t, th.SystemAdminUser.Id, token.CreatorId)
+	assert.NotEmpty(t, token.Token)
+
+	_, resp = th.Client.GetProvisioningTokens(0, 100)
+	CheckForbiddenStatus(t, resp)
+
+	tokens, resp := th.SystemAdminClient.GetProvisioningTokens(0, 100)
+	CheckNoError(t, resp)
+	found := false
+	for _, listed := range tokens {
+		if listed.Id == token.Id {
+			found = true
+			assert.Empty(t, listed.Token)
+		}
+	}
+	assert.True(t, found)
+
+	_, resp = th.Client.RevokeProvisioningToken(token.Id)
+	CheckForbiddenStatus(t, resp)
+
+	ok, resp := th.SystemAdminClient.RevokeProvisioningToken(token.Id)
+	CheckNoError(t, resp)
+	require.True(t, ok)
+
+	_, err := th.App.AuthenticateProvisioningToken(token.Token)
+	assert.NotNil(t, err)
+}
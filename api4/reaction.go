@@ -33,7 +33,7 @@ func saveReaction(c *Context, w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !c.App.SessionHasPermissionToChannelByPost(c.Session, reaction.PostId, model.PERMISSION_ADD_REACTION) {
-		c.SetPermissionError(model.PERMISSION_ADD_REACTION)
+		setChannelPermissionErrorByPost(c, reaction.PostId, model.PERMISSION_ADD_REACTION)
 		return
 	}
 
@@ -83,11 +83,11 @@ func deleteReaction(c *Context, w http.ResponseWriter, r *http.Request) {
 	}
 
 	if !c.App.SessionHasPermissionToChannelByPost(c.Session, c.Params.PostId, model.PERMISSION_REMOVE_REACTION) {
-		c.SetPermissionError(model.PERMISSION_REMOVE_REACTION)
+		setChannelPermissionErrorByPost(c, c.Params.PostId, model.PERMISSION_REMOVE_REACTION)
 		return
 	}
 
-	if c.Params.UserId != c.Session.UserId && !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_REMOVE_OTHERS_REACTIONS) {
+	if c.Params.UserId != c.Session.UserId && !c.App.SessionHasPermissionToChannelByPost(c.Session, c.Params.PostId, model.PERMISSION_REMOVE_OTHERS_REACTIONS) {
 		c.SetPermissionError(model.PERMISSION_REMOVE_OTHERS_REACTIONS)
 		return
 	}
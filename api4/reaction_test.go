@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/mattermost/mattermost-server/model"
 )
@@ -172,6 +173,27 @@ func TestSaveReaction(t *testing.T) {
 		}
 		th.AddPermissionToRole(model.PERMISSION_ADD_REACTION.Id, model.CHANNEL_USER_ROLE_ID)
 	})
+
+	t.Run("unique-emoji-limit", func(t *testing.T) {
+		th.LoginBasic()
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.UniqueEmojiReactionLimitPerPost = 2 })
+		defer th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.UniqueEmojiReactionLimitPerPost = 50 })
+
+		post := th.CreatePost()
+
+		_, resp := Client.SaveReaction(&model.Reaction{UserId: userId, PostId: post.Id, EmojiName: "smile"})
+		CheckNoError(t, resp)
+		_, resp = Client.SaveReaction(&model.Reaction{UserId: userId, PostId: post.Id, EmojiName: "sad"})
+		CheckNoError(t, resp)
+
+		_, resp = Client.SaveReaction(&model.Reaction{UserId: userId, PostId: post.Id, EmojiName: "angry"})
+		CheckBadRequestStatus(t, resp)
+		CheckErrorMessage(t, resp, "app.reaction.save.unique_emoji_limit.app_error")
+
+		// other users can still react with the emoji that are already on the post
+		_, resp = th.SystemAdminClient.SaveReaction(&model.Reaction{UserId: th.SystemAdminUser.Id, PostId: post.Id, EmojiName: "sad"})
+		CheckNoError(t, resp)
+	})
 }
 
 func TestGetReactions(t *testing.T) {
@@ -452,3 +474,88 @@ func TestDeleteReaction(t *testing.T) {
 		th.AddPermissionToRole(model.PERMISSION_REMOVE_OTHERS_REACTIONS.Id, model.SYSTEM_ADMIN_ROLE_ID)
 	})
 }
+
+func TestReactionsDisabledForChannel(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	post := th.CreatePost()
+	existing := &model.Reaction{UserId: th.BasicUser.Id, PostId: post.Id, EmojiName: "smile"}
+	_, resp := Client.SaveReaction(existing)
+	CheckNoError(t, resp)
+
+	_, resp = th.SystemAdminClient.PatchChannel(th.BasicChannel.Id, &model.ChannelPatch{DisableReactions: model.NewBool(true)})
+	CheckNoError(t, resp)
+
+	t.Run("member", func(t *testing.T) {
+		_, resp := Client.SaveReaction(&model.Reaction{UserId: th.BasicUser.Id, PostId: post.Id, EmojiName: "sad"})
+		CheckForbiddenStatus(t, resp)
+		CheckErrorMessage(t, resp, "app.reaction.save_reaction.disabled.app_error")
+	})
+
+	t.Run("channel admin", func(t *testing.T) {
+		th.MakeUserChannelAdmin(th.BasicUser2, th.BasicChannel)
+
+		th.LoginBasic2()
+		defer th.LoginBasic()
+
+		_, resp := Client.SaveReaction(&model.Reaction{UserId: th.BasicUser2.Id, PostId: post.Id, EmojiName: "sad"})
+		CheckForbiddenStatus(t, resp)
+		CheckErrorMessage(t, resp, "app.reaction.save_reaction.disabled.app_error")
+	})
+
+	t.Run("system admin", func(t *testing.T) {
+		_, resp := th.SystemAdminClient.SaveReaction(&model.Reaction{UserId: th.SystemAdminUser.Id, PostId: post.Id, EmojiName: "sad"})
+		CheckForbiddenStatus(t, resp)
+		CheckErrorMessage(t, resp, "app.reaction.save_reaction.disabled.app_error")
+	})
+
+	t.Run("removing existing reactions", func(t *testing.T) {
+		_, resp := Client.DeleteReaction(existing)
+		CheckNoError(t, resp)
+	})
+
+	_, resp = th.SystemAdminClient.PatchChannel(th.BasicChannel.Id, &model.ChannelPatch{DisableReactions: model.NewBool(false)})
+	CheckNoError(t, resp)
+
+	_, resp = Client.SaveReaction(existing)
+	CheckNoError(t, resp)
+}
+
+func TestDeleteOthersReactionInChannel(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	post := th.CreatePost()
+	reaction := &model.Reaction{UserId: th.BasicUser.Id, PostId: post.Id, EmojiName: "smile"}
+	_, resp := Client.SaveReaction(reaction)
+	CheckNoError(t, resp)
+
+	th.LoginBasic2()
+	defer th.LoginBasic()
+
+	_, resp = Client.DeleteReaction(reaction)
+	CheckForbiddenStatus(t, resp)
+
+	th.MakeUserChannelAdmin(th.BasicUser2, th.BasicChannel)
+
+	_, resp = Client.DeleteReaction(reaction)
+	CheckNoError(t, resp)
+
+	reactions, err := th.App.GetReactionsForPost(post.Id)
+	require.Nil(t, err)
+	assert.Empty(t, reactions)
+
+	// Being an admin of one channel doesn't let a user remove reactions in another
+	otherPost, appErr := th.App.CreatePost(&model.Post{UserId: th.BasicUser.Id, ChannelId: th.BasicChannel2.Id, Message: "message"}, th.BasicChannel2, false)
+	require.Nil(t, appErr)
+
+	otherReaction := &model.Reaction{UserId: th.BasicUser.Id, PostId: otherPost.Id, EmojiName: "smile"}
+	_, appErr = th.App.SaveReactionForPost(otherReaction)
+	require.Nil(t, appErr)
+
+	_, resp = Client.DeleteReaction(otherReaction)
+	CheckForbiddenStatus(t, resp)
+}
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/mattermost/mattermost-server/model"
 )
@@ -212,3 +213,147 @@ func TestPatchRole(t *testing.T) {
 	assert.EqualValues(t, received.Permissions, []string{"manage_system", "manage_webhooks"})
 	assert.Equal(t, received.SchemeManaged, role.SchemeManaged)
 }
+
+func loginWithSystemRole(t *testing.T, th *TestHelper, roleName string) (*model.Client4, *model.User) {
+	user := th.CreateUser()
+	_, err := th.App.UpdateUserRoles(user.Id, model.SYSTEM_USER_ROLE_ID+" "+roleName, false)
+	require.Nil(t, err)
+
+	client := th.CreateClient()
+	_, resp := client.Login(user.Email, user.Password)
+	CheckNoError(t, resp)
+
+	return client, user
+}
+
+func TestReadOnlyAdminRole(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		cfg.GoogleSettings.Secret = "google-secret"
+	})
+
+	client, _ := loginWithSystemRole(t, th, model.SYSTEM_READ_ONLY_ADMIN_ROLE_ID)
+
+	t.Run("config is readable with secrets hidden", func(t *testing.T) {
+		cfg, resp := client.GetConfig()
+		CheckNoError(t, resp)
+		assert.Equal(t, model.FAKE_SETTING, *cfg.SqlSettings.DataSource)
+		assert.Equal(t, model.FAKE_SETTING, *cfg.FileSettings.PublicLinkSalt)
+		assert.Equal(t, model.FAKE_SETTING, cfg.EmailSettings.InviteSalt)
+		assert.Equal(t, model.FAKE_SETTING, cfg.GoogleSettings.Secret)
+		assert.Equal(t, th.App.Config().TeamSettings.SiteName, cfg.TeamSettings.SiteName)
+
+		cfg, resp = th.SystemAdminClient.GetConfig()
+		CheckNoError(t, resp)
+		assert.Equal(t, "google-secret", cfg.GoogleSettings.Secret, "system admins should still see the secrets they can change")
+
+		_, resp = client.UpdateConfig(cfg)
+		CheckForbiddenStatus(t, resp)
+	})
+
+	t.Run("system console pages are readable", func(t *testing.T) {
+		_, resp := client.GetAudits(0, 10, "")
+		CheckNoError(t, resp)
+
+		_, resp = client.GetClusterStatus()
+		CheckNoError(t, resp)
+
+		_, resp = client.GetUsersStats(&model.UserFilter{})
+		CheckNoError(t, resp)
+
+		_, resp = th.Client.GetAudits(0, 10, "")
+		CheckForbiddenStatus(t, resp)
+	})
+
+	t.Run("users can't be changed", func(t *testing.T) {
+		_, resp := client.UpdateUserActive(th.BasicUser2.Id, false)
+		CheckForbiddenStatus(t, resp)
+
+		_, resp = client.PatchUser(th.BasicUser2.Id, &model.UserPatch{Nickname: model.NewString("changed")})
+		CheckForbiddenStatus(t, resp)
+	})
+}
+
+func TestUserManagerRole(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+
+	client, _ := loginWithSystemRole(t, th, model.SYSTEM_USER_MANAGER_ROLE_ID)
+
+	t.Run("config isn't readable", func(t *testing.T) {
+		_, resp := client.GetConfig()
+		CheckForbiddenStatus(t, resp)
+
+		_, resp = client.GetAudits(0, 10, "")
+		CheckForbiddenStatus(t, resp)
+	})
+
+	t.Run("users can be managed", func(t *testing.T) {
+		user := th.CreateUser()
+
+		_, resp := client.PatchUser(user.Id, &model.UserPatch{Nickname: model.NewString("changed")})
+		CheckNoError(t, resp)
+
+		_, resp = client.UpdateUserPassword(user.Id, "", "Password2")
+		CheckNoError(t, resp)
+
+		_, resp = client.AddTeamMember(th.BasicTeam.Id, user.Id)
+		CheckNoError(t, resp)
+
+		_, resp = client.RemoveTeamMember(th.BasicTeam.Id, user.Id)
+		CheckNoError(t, resp)
+
+		_, resp = client.UpdateUserActive(user.Id, false)
+		CheckNoError(t, resp)
+
+		_, resp = client.GetUsersStats(&model.UserFilter{})
+		CheckNoError(t, resp)
+	})
+
+	t.Run("system admins can't be managed", func(t *testing.T) {
+		_, resp := client.PatchUser(th.SystemAdminUser.Id, &model.UserPatch{Email: model.NewString(th.GenerateTestEmail())})
+		CheckForbiddenStatus(t, resp)
+
+		_, resp = client.UpdateUserPassword(th.SystemAdminUser.Id, "", "Password2")
+		CheckForbiddenStatus(t, resp)
+
+		_, resp = client.UpdateUserActive(th.SystemAdminUser.Id, false)
+		CheckForbiddenStatus(t, resp)
+	})
+
+	t.Run("users with other privileged roles can't be managed", func(t *testing.T) {
+		for _, roleName := range []string{model.SYSTEM_READ_ONLY_ADMIN_ROLE_ID, model.SYSTEM_USER_MANAGER_ROLE_ID} {
+			_, privileged := loginWithSystemRole(t, th, roleName)
+
+			_, resp := client.PatchUser(privileged.Id, &model.UserPatch{Email: model.NewString(th.GenerateTestEmail())})
+			CheckForbiddenStatus(t, resp)
+
+			_, resp = client.UpdateUserPassword(privileged.Id, "", "Password2")
+			CheckForbiddenStatus(t, resp)
+
+			_, resp = client.UpdateUserActive(privileged.Id, false)
+			CheckForbiddenStatus(t, resp)
+
+			_, resp = client.DeleteUser(privileged.Id)
+			CheckForbiddenStatus(t, resp)
+		}
+	})
+
+	t.Run("what users have posted or set isn't readable", func(t *testing.T) {
+		_, resp := client.GetFlaggedPostsForUser(th.BasicUser.Id, 0, 10)
+		CheckForbiddenStatus(t, resp)
+
+		_, resp = client.GetPreferences(th.BasicUser.Id)
+		CheckForbiddenStatus(t, resp)
+
+		_, resp = client.GetSessions(th.BasicUser.Id, "")
+		CheckForbiddenStatus(t, resp)
+	})
+
+	t.Run("roles can't be assigned", func(t *testing.T) {
+		_, resp := client.UpdateUserRoles(th.BasicUser2.Id, model.SYSTEM_USER_ROLE_ID+" "+model.SYSTEM_ADMIN_ROLE_ID)
+		CheckForbiddenStatus(t, resp)
+	})
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func (api *API) InitSavedSearch() {
+	api.BaseRoutes.SavedSearches.Handle("", api.ApiSessionRequired(createSavedSearch)).Methods("POST")
+	api.BaseRoutes.SavedSearches.Handle("", api.ApiSessionRequired(getSavedSearches)).Methods("GET")
+	api.BaseRoutes.SavedSearches.Handle("/{saved_search_id:[A-Za-z0-9]+}", api.ApiSessionRequired(deleteSavedSearch)).Methods("DELETE")
+}
+
+func createSavedSearch(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId()
+	if c.Err != nil {
+		return
+	}
+
+	search := model.SavedSearchFromJson(r.Body)
+	if search == nil {
+		c.SetInvalidParam("saved_search")
+		return
+	}
+
+	if !c.App.SessionHasPermissionToUser(c.Session, c.Params.UserId) {
+		c.SetPermissionError(model.PERMISSION_EDIT_OTHER_USERS)
+		return
+	}
+
+	saved, err := c.App.CreateSavedSearch(c.Params.UserId, search)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(saved.ToJson()))
+}
+
+func getSavedSearches(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionToUser(c.Session, c.Params.UserId) {
+		c.SetPermissionError(model.PERMISSION_EDIT_OTHER_USERS)
+		return
+	}
+
+	searches, err := c.App.GetSavedSearches(c.Params.UserId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(model.SavedSearchListToJson(searches)))
+}
+
+func deleteSavedSearch(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId().RequireSavedSearchId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionToUser(c.Session, c.Params.UserId) {
+		c.SetPermissionError(model.PERMISSION_EDIT_OTHER_USERS)
+		return
+	}
+
+	if err := c.App.DeleteSavedSearch(c.Params.UserId, c.Params.SavedSearchId); err != nil {
+		c.Err = err
+		return
+	}
+
+	ReturnStatusOK(w)
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestSavedSearches(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	searches, resp := Client.GetSavedSearches(th.BasicUser.Id)
+	CheckNoError(t, resp)
+	assert.Len(t, searches, 0)
+
+	standup, resp := Client.CreateSavedSearch(th.BasicUser.Id, &model.SavedSearch{Name: "Standup", Terms: "#standup"})
+	CheckNoError(t, resp)
+	CheckCreatedStatus(t, resp)
+	require.Len(t, standup.Id, 26)
+	assert.Equal(t, "Standup", standup.Name)
+	assert.Equal(t, "#standup", standup.Terms)
+
+	mine, resp := Client.CreateSavedSearch(th.BasicUser.Id, &model.SavedSearch{Name: "Mine", Terms: "from:" + th.BasicUser.Username, IsOrSearch: true})
+	CheckNoError(t, resp)
+
+	searches, resp = Client.GetSavedSearches(th.BasicUser.Id)
+	CheckNoError(t, resp)
+	require.Len(t, searches, 2)
+	assert.Equal(t, standup.Id, searches[0].Id)
+	assert.Equal(t, mine.Id, searches[1].Id)
+	assert.True(t, searches[1].IsOrSearch)
+
+	t.Run("duplicate name", func(t *testing.T) {
+		_, resp := Client.CreateSavedSearch(th.BasicUser.Id, &model.SavedSearch{Name: "standup", Terms: "standup"})
+		CheckBadRequestStatus(t, resp)
+		CheckErrorMessage(t, resp, "app.saved_search.create.duplicate_name.app_error")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, resp := Client.CreateSavedSearch(th.BasicUser.Id, &model.SavedSearch{Name: "empty"})
+		CheckBadRequestStatus(t, resp)
+	})
+
+	t.Run("other users", func(t *testing.T) {
+		_, resp := Client.GetSavedSearches(th.BasicUser2.Id)
+		CheckForbiddenStatus(t, resp)
+
+		_, resp = Client.CreateSavedSearch(th.BasicUser2.Id, &model.SavedSearch{Name: "theirs", Terms: "theirs"})
+		CheckForbiddenStatus(t, resp)
+
+		_, resp = Client.DeleteSavedSearch(th.BasicUser2.Id, standup.Id)
+		CheckForbiddenStatus(t, resp)
+
+		searches, resp := th.SystemAdminClient.GetSavedSearches(th.BasicUser.Id)
+		CheckNoError(t, resp)
+		assert.Len(t, searches, 2)
+	})
+
+	t.Run("not changeable as preferences", func(t *testing.T) {
+		_, resp := Client.UpdatePreferences(th.BasicUser.Id, &model.Preferences{*standup.ToPreference(th.BasicUser.Id)})
+		CheckBadRequestStatus(t, resp)
+	})
+
+	ok, resp := Client.DeleteSavedSearch(th.BasicUser.Id, standup.Id)
+	CheckNoError(t, resp)
+	assert.True(t, ok)
+
+	_, resp = Client.DeleteSavedSearch(th.BasicUser.Id, standup.Id)
+	CheckNotFoundStatus(t, resp)
+
+	searches, resp = Client.GetSavedSearches(th.BasicUser.Id)
+	CheckNoError(t, resp)
+	require.Len(t, searches, 1)
+	assert.Equal(t, mine.Id, searches[0].Id)
+}
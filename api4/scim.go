@@ -0,0 +1,305 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func (api *API) InitScim() {
+	api.BaseRoutes.Scim.Handle("/ServiceProviderConfig", api.scimHandler(getScimServiceProviderConfig)).Methods("GET")
+
+	api.BaseRoutes.Scim.Handle("/Users", api.scimHandler(createScimUser)).Methods("POST")
+	api.BaseRoutes.Scim.Handle("/Users", api.scimHandler(getScimUsers)).Methods("GET")
+	api.BaseRoutes.Scim.Handle("/Users/{user_id:[A-Za-z0-9]+}", api.scimHandler(getScimUser)).Methods("GET")
+	api.BaseRoutes.Scim.Handle("/Users/{user_id:[A-Za-z0-9]+}", api.scimHandler(replaceScimUser)).Methods("PUT")
+	api.BaseRoutes.Scim.Handle("/Users/{user_id:[A-Za-z0-9]+}", api.scimHandler(patchScimUser)).Methods("PATCH")
+	api.BaseRoutes.Scim.Handle("/Users/{user_id:[A-Za-z0-9]+}", api.scimHandler(deleteScimUser)).Methods("DELETE")
+
+	api.BaseRoutes.Scim.Handle("/Groups", api.scimHandler(createScimGroup)).Methods("POST")
+	api.BaseRoutes.Scim.Handle("/Groups", api.scimHandler(getScimGroups)).Methods("GET")
+	api.BaseRoutes.Scim.Handle("/Groups/{group_id:[A-Za-z0-9]+}", api.scimHandler(getScimGroup)).Methods("GET")
+	api.BaseRoutes.Scim.Handle("/Groups/{group_id:[A-Za-z0-9]+}", api.scimHandler(replaceScimGroup)).Methods("PUT")
+	api.BaseRoutes.Scim.Handle("/Groups/{group_id:[A-Za-z0-9]+}", api.scimHandler(patchScimGroup)).Methods("PATCH")
+	api.BaseRoutes.Scim.Handle("/Groups/{group_id:[A-Za-z0-9]+}", api.scimHandler(deleteScimGroup)).Methods("DELETE")
+}
+
+// scimHandler authenticates SCIM requests with a provisioning token instead of a session and reports errors the
+// way that SCIM clients expect them.
+func (api *API) scimHandler(h func(*Context, http.ResponseWriter, *http.Request, *model.ProvisioningToken)) http.Handler {
+	return api.ApiHandler(func(c *Context, w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", model.SCIM_CONTENT_TYPE)
+
+		if !*c.App.Config().ScimSettings.Enable {
+			c.Err = model.NewAppError("scimHandler", "api.scim.disabled.app_error", nil, "", http.StatusNotImplemented)
+		} else {
+			authHeader := r.Header.Get(model.HEADER_AUTH)
+			tokenString := ""
+			if len(authHeader) > 7 && strings.ToUpper(authHeader[0:6]) == model.HEADER_BEARER {
+				tokenString = strings.TrimSpace(authHeader[7:])
+			}
+
+			if token, err := c.App.AuthenticateProvisioningToken(tokenString); err != nil {
+				c.Err = err
+			} else {
+				h(c, w, r, token)
+			}
+		}
+
+		if c.Err != nil {
+			c.Err.Translate(c.T)
+			c.LogError(c.Err)
+
+			w.WriteHeader(c.Err.StatusCode)
+			w.Write([]byte(model.NewScimError(c.Err, scimErrorType(c.Err)).ToJson()))
+
+			c.Err = nil
+		}
+	})
+}
+
+// scimErrorType returns the SCIM error type that best describes an error, if there is one.
+func scimErrorType(err *model.AppError) string {
+	switch {
+	case err.StatusCode == http.StatusConflict:
+		return model.SCIM_ERROR_TYPE_UNIQUENESS
+	case err.Id == "model.scim.parse_filter.app_error" || err.Id == "app.scim.filter.unsupported_attribute.app_error":
+		return model.SCIM_ERROR_TYPE_INVALID_FILTER
+	case err.Id == "model.scim.patch.invalid_path.app_error":
+		return model.SCIM_ERROR_TYPE_INVALID_PATH
+	case err.Id == "model.scim.patch.no_target.app_error":
+		return model.SCIM_ERROR_TYPE_NO_TARGET
+	case err.Id == "api.context.invalid_body_param.app_error":
+		return model.SCIM_ERROR_TYPE_INVALID_SYNTAX
+	case err.StatusCode == http.StatusBadRequest:
+		return model.SCIM_ERROR_TYPE_INVALID_VALUE
+	}
+
+	return ""
+}
+
+func writeScimResource(w http.ResponseWriter, status int, meta *model.ScimMeta, json string) {
+	w.Header().Set(model.HEADER_ETAG_SERVER, meta.Version)
+	w.Header().Set("Location", meta.Location)
+	w.WriteHeader(status)
+	w.Write([]byte(json))
+}
+
+// scimPaging returns the 1-based index of the first result and the number of results requested by a SCIM query.
+func scimPaging(r *http.Request) (int, int) {
+	query := r.URL.Query()
+
+	startIndex, err := strconv.Atoi(query.Get("startIndex"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+
+	count, err := strconv.Atoi(query.Get("count"))
+	if err != nil || count < 0 {
+		count = model.SCIM_DEFAULT_COUNT
+	} else if count > model.SCIM_MAX_COUNT {
+		count = model.SCIM_MAX_COUNT
+	}
+
+	return startIndex, count
+}
+
+func scimFilter(c *Context, r *http.Request) (*model.ScimFilter, bool) {
+	query := r.URL.Query().Get("filter")
+	if query == "" {
+		return nil, true
+	}
+
+	filter, err := model.ParseScimFilter(query)
+	if err != nil {
+		c.Err = err
+		return nil, false
+	}
+
+	return filter, true
+}
+
+func getScimServiceProviderConfig(c *Context, w http.ResponseWriter, r *http.Request, token *model.ProvisioningToken) {
+	w.Write([]byte(model.NewScimServiceProviderConfig().ToJson()))
+}
+
+func createScimUser(c *Context, w http.ResponseWriter, r *http.Request, token *model.ProvisioningToken) {
+	scimUser := model.ScimUserFromJson(r.Body)
+	if scimUser == nil {
+		c.SetInvalidParam("user")
+		return
+	}
+
+	user, err := c.App.CreateScimUser(scimUser)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAuditWithUserId(token.CreatorId, "scim user_id="+user.Id)
+	writeScimResource(w, http.StatusCreated, user.Meta, user.ToJson())
+}
+
+func getScimUsers(c *Context, w http.ResponseWriter, r *http.Request, token *model.ProvisioningToken) {
+	filter, ok := scimFilter(c, r)
+	if !ok {
+		return
+	}
+
+	startIndex, count := scimPaging(r)
+
+	list, err := c.App.SearchScimUsers(filter, startIndex, count)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(list.ToJson()))
+}
+
+func getScimUser(c *Context, w http.ResponseWriter, r *http.Request, token *model.ProvisioningToken) {
+	user, err := c.App.GetScimUser(c.Params.UserId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	writeScimResource(w, http.StatusOK, user.Meta, user.ToJson())
+}
+
+func replaceScimUser(c *Context, w http.ResponseWriter, r *http.Request, token *model.ProvisioningToken) {
+	scimUser := model.ScimUserFromJson(r.Body)
+	if scimUser == nil {
+		c.SetInvalidParam("user")
+		return
+	}
+
+	user, err := c.App.ReplaceScimUser(c.Params.UserId, scimUser)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAuditWithUserId(token.CreatorId, "scim user_id="+user.Id)
+	writeScimResource(w, http.StatusOK, user.Meta, user.ToJson())
+}
+
+func patchScimUser(c *Context, w http.ResponseWriter, r *http.Request, token *model.ProvisioningToken) {
+	patch := model.ScimPatchRequestFromJson(r.Body)
+	if patch == nil {
+		c.SetInvalidParam("patch")
+		return
+	}
+
+	user, err := c.App.PatchScimUser(c.Params.UserId, patch)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAuditWithUserId(token.CreatorId, "scim user_id="+user.Id)
+	writeScimResource(w, http.StatusOK, user.Meta, user.ToJson())
+}
+
+func deleteScimUser(c *Context, w http.ResponseWriter, r *http.Request, token *model.ProvisioningToken) {
+	if err := c.App.DeactivateScimUser(c.Params.UserId); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAuditWithUserId(token.CreatorId, "scim user_id="+c.Params.UserId)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func createScimGroup(c *Context, w http.ResponseWriter, r *http.Request, token *model.ProvisioningToken) {
+	scimGroup := model.ScimGroupFromJson(r.Body)
+	if scimGroup == nil {
+		c.SetInvalidParam("group")
+		return
+	}
+
+	group, err := c.App.CreateScimGroup(scimGroup, token.CreatorId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAuditWithUserId(token.CreatorId, "scim group_id="+group.Id)
+	writeScimResource(w, http.StatusCreated, group.Meta, group.ToJson())
+}
+
+func getScimGroups(c *Context, w http.ResponseWriter, r *http.Request, token *model.ProvisioningToken) {
+	filter, ok := scimFilter(c, r)
+	if !ok {
+		return
+	}
+
+	startIndex, count := scimPaging(r)
+
+	list, err := c.App.SearchScimGroups(filter, startIndex, count)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(list.ToJson()))
+}
+
+func getScimGroup(c *Context, w http.ResponseWriter, r *http.Request, token *model.ProvisioningToken) {
+	group, err := c.App.GetScimGroup(c.Params.GroupId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	writeScimResource(w, http.StatusOK, group.Meta, group.ToJson())
+}
+
+func replaceScimGroup(c *Context, w http.ResponseWriter, r *http.Request, token *model.ProvisioningToken) {
+	scimGroup := model.ScimGroupFromJson(r.Body)
+	if scimGroup == nil {
+		c.SetInvalidParam("group")
+		return
+	}
+
+	group, err := c.App.ReplaceScimGroup(c.Params.GroupId, scimGroup)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAuditWithUserId(token.CreatorId, "scim group_id="+group.Id)
+	writeScimResource(w, http.StatusOK, group.Meta, group.ToJson())
+}
+
+func patchScimGroup(c *Context, w http.ResponseWriter, r *http.Request, token *model.ProvisioningToken) {
+	patch := model.ScimPatchRequestFromJson(r.Body)
+	if patch == nil {
+		c.SetInvalidParam("patch")
+		return
+	}
+
+	group, err := c.App.PatchScimGroup(c.Params.GroupId, patch)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAuditWithUserId(token.CreatorId, "scim group_id="+group.Id)
+	writeScimResource(w, http.StatusOK, group.Meta, group.ToJson())
+}
+
+func deleteScimGroup(c *Context, w http.ResponseWriter, r *http.Request, token *model.ProvisioningToken) {
+	if err := c.App.DeleteScimGroup(c.Params.GroupId); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAuditWithUserId(token.CreatorId, "scim group_id="+c.Params.GroupId)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,232 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+type scimClient struct {
+	th    *TestHelper
+	token string
+}
+
+func (c *scimClient) do(t *testing.T, method string, path string, body string) *http.Response {
+	r, err := http.NewRequest(method, c.th.Client.Url+model.SCIM_URL_SUFFIX+path, strings.NewReader(body))
+	require.Nil(t, err)
+
+	r.Header.Set("Content-Type", model.SCIM_CONTENT_TYPE)
+	if c.token != "" {
+		r.Header.Set(model.HEADER_AUTH, "Bearer "+c.token)
+	}
+
+	resp, err := c.th.Client.HttpClient.Do(r)
+	require.Nil(t, err)
+
+	return resp
+}
+
+func setupScim(t *testing.T) (*TestHelper, *scimClient) {
+	th := Setup().InitBasic().InitSystemAdmin()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ScimSettings.Enable = true })
+
+	token, err := th.App.CreateProvisioningToken(&model.ProvisioningToken{CreatorId: th.SystemAdminUser.Id, Description: "test"})
+	require.Nil(t, err)
+
+	return th, &scimClient{th: th, token: token.Token}
+}
+
+func scimErrorFromResponse(t *testing.T, resp *http.Response) map[string]interface{} {
+	defer resp.Body.Close()
+
+	assert.Equal(t, model.SCIM_CONTENT_TYPE, resp.Header.Get("Content-Type"))
+	return model.StringInterfaceFromJson(resp.Body)
+}
+
+func TestScimAuthentication(t *testing.T) {
+	th, client := setupScim(t)
+	defer th.TearDown()
+
+	resp := client.do(t, "GET", "/ServiceProviderConfig", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	resp = (&scimClient{th: th}).do(t, "GET", "/Users", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "401", scimErrorFromResponse(t, resp)["status"])
+
+	resp = (&scimClient{th: th, token: model.NewId()}).do(t, "GET", "/Users", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp.Body.Close()
+
+	// a session token isn't a provisioning token
+	resp = (&scimClient{th: th, token: th.SystemAdminClient.AuthToken}).do(t, "GET", "/Users", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp.Body.Close()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ScimSettings.Enable = false })
+
+	resp = client.do(t, "GET", "/Users", "")
+	assert.Equal(t, http.StatusNotImplemented, resp.StatusCode)
+	resp.Body.Close()
+}
+
+func TestScimUsers(t *testing.T) {
+	th, client := setupScim(t)
+	defer th.TearDown()
+
+	userName := "bjensen" + model.NewId() + "@example.com"
+	body := `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+		"userName": "` + userName + `",
+		"name": {"givenName": "Barbara", "familyName": "Jensen"},
+		"emails": [{"value": "` + userName + `", "type": "work", "primary": true}],
+		"active": true
+	}`
+
+	resp := client.do(t, "POST", "/Users", body)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	created := model.ScimUserFromJson(resp.Body)
+	resp.Body.Close()
+	require.NotNil(t, created)
+	assert.Equal(t, userName, created.UserName)
+	assert.Equal(t, created.Meta.Version, resp.Header.Get(model.HEADER_ETAG_SERVER))
+	assert.Equal(t, created.Meta.Location, resp.Header.Get("Location"))
+
+	t.Run("create again", func(t *testing.T) {
+		resp := client.do(t, "POST", "/Users", body)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+		assert.Equal(t, model.SCIM_ERROR_TYPE_UNIQUENESS, scimErrorFromResponse(t, resp)["scimType"])
+	})
+
+	t.Run("get", func(t *testing.T) {
+		resp := client.do(t, "GET", "/Users/"+created.Id, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		fetched := model.ScimUserFromJson(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, created.Id, fetched.Id)
+		assert.Equal(t, created.Meta.Version, resp.Header.Get(model.HEADER_ETAG_SERVER))
+
+		resp = client.do(t, "GET", "/Users/"+model.NewId(), "")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		resp.Body.Close()
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		resp := client.do(t, "GET", "/Users?filter="+`userName%20eq%20%22`+userName+`%22`, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		list := model.StringInterfaceFromJson(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, float64(1), list["totalResults"])
+
+		resp = client.do(t, "GET", "/Users?filter=userName%20sw%20%22b%22", "")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, model.SCIM_ERROR_TYPE_INVALID_FILTER, scimErrorFromResponse(t, resp)["scimType"])
+	})
+
+	t.Run("patch", func(t *testing.T) {
+		resp := client.do(t, "PATCH", "/Users/"+created.Id, `{
+			"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+			"Operations": [{"op": "replace", "path": "name.familyName", "value": "Smith"}]
+		}`)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		patched := model.ScimUserFromJson(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, "Smith", patched.Name.FamilyName)
+
+		user, err := th.App.GetUser(created.Id)
+		require.Nil(t, err)
+		assert.Equal(t, "Smith", user.LastName)
+
+		resp = client.do(t, "PATCH", "/Users/"+created.Id, `{"Operations": [{"op": "replace", "path": "emails[", "value": "x"}]}`)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, model.SCIM_ERROR_TYPE_INVALID_PATH, scimErrorFromResponse(t, resp)["scimType"])
+	})
+
+	t.Run("delete and provision again", func(t *testing.T) {
+		resp := client.do(t, "DELETE", "/Users/"+created.Id, "")
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		resp.Body.Close()
+
+		user, err := th.App.GetUser(created.Id)
+		require.Nil(t, err)
+		assert.NotEqual(t, int64(0), user.DeleteAt)
+
+		resp = client.do(t, "POST", "/Users", body)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		reprovisioned := model.ScimUserFromJson(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, created.Id, reprovisioned.Id)
+		assert.True(t, *reprovisioned.Active)
+	})
+
+	t.Run("users who weren't provisioned can't be changed", func(t *testing.T) {
+		for _, user := range []*model.User{th.BasicUser, th.SystemAdminUser} {
+			resp := client.do(t, "PUT", "/Users/"+user.Id, body)
+			assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+			resp.Body.Close()
+
+			resp = client.do(t, "DELETE", "/Users/"+user.Id, "")
+			assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+			resp.Body.Close()
+
+			unchanged, err := th.App.GetUser(user.Id)
+			require.Nil(t, err)
+			assert.Equal(t, user.Email, unchanged.Email)
+			assert.Equal(t, int64(0), unchanged.DeleteAt)
+		}
+	})
+}
+
+func TestScimGroups(t *testing.T) {
+	th, client := setupScim(t)
+	defer th.TearDown()
+
+	resp := client.do(t, "POST", "/Groups", `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"],
+		"displayName": "Engineering",
+		"members": [{"value": "`+th.BasicUser.Id+`"}]
+	}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	created := model.ScimGroupFromJson(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, []string{th.BasicUser.Id}, created.MemberIds())
+	assert.Equal(t, created.Meta.Version, resp.Header.Get(model.HEADER_ETAG_SERVER))
+
+	group, err := th.App.GetCustomGroup(created.Id)
+	require.Nil(t, err)
+	assert.Equal(t, "Engineering", group.DisplayName)
+	assert.Equal(t, th.SystemAdminUser.Id, group.CreatorId)
+
+	resp = client.do(t, "PATCH", "/Groups/"+created.Id, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [{"op": "add", "path": "members", "value": [{"value": "`+th.BasicUser2.Id+`"}]}]
+	}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	patched := model.ScimGroupFromJson(resp.Body)
+	resp.Body.Close()
+	assert.ElementsMatch(t, []string{th.BasicUser.Id, th.BasicUser2.Id}, patched.MemberIds())
+
+	resp = client.do(t, "GET", "/Groups?filter=displayName%20eq%20%22Engineering%22", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	list := model.StringInterfaceFromJson(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, float64(1), list["totalResults"])
+
+	resp = client.do(t, "DELETE", "/Groups/"+created.Id, "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp.Body.Close()
+
+	resp = client.do(t, "GET", "/Groups/"+created.Id, "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp.Body.Close()
+}
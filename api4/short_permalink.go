@@ -0,0 +1,51 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+func (api *API) InitShortPermalink() {
+	api.BaseRoutes.Root.Handle(model.SHORT_PERMALINK_PATH+"{permalink_code:[A-Za-z0-9]+}", api.ApiHandlerTrustRequester(followShortPermalink)).Methods("GET")
+}
+
+// followShortPermalink redirects a user who followed a short permalink from a notification to the post. Errors are
+// shown on the error page since the link is opened in a browser.
+func followShortPermalink(c *Context, w http.ResponseWriter, r *http.Request) {
+	if !*c.App.Config().ServiceSettings.EnableShortPermalinks {
+		err := model.NewAppError("followShortPermalink", "app.short_permalink.disabled.app_error", nil, "", http.StatusNotImplemented)
+		err.Translate(c.T)
+		utils.RenderWebAppError(w, r, err, c.App.AsymmetricSigningKey())
+		return
+	}
+
+	if len(c.Session.UserId) == 0 {
+		http.Redirect(w, r, c.GetSiteURLHeader()+"/login?redirect_to="+url.QueryEscape(r.RequestURI), http.StatusFound)
+		return
+	}
+
+	err := c.App.CheckShortPermalinkRateLimit("user:" + c.Session.UserId)
+	if err == nil {
+		err = c.App.CheckShortPermalinkRateLimit("ip:" + c.IpAddress)
+	}
+	if err != nil {
+		err.Translate(c.T)
+		utils.RenderWebAppError(w, r, err, c.App.AsymmetricSigningKey())
+		return
+	}
+
+	permalink, err := c.App.ResolveShortPermalink(c.Params.PermalinkCode, &c.Session)
+	if err != nil {
+		err.Translate(c.T)
+		utils.RenderWebAppError(w, r, err, c.App.AsymmetricSigningKey())
+		return
+	}
+
+	http.Redirect(w, r, permalink, http.StatusFound)
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func followTestShortPermalink(t *testing.T, client *model.Client4, link string) *http.Response {
+	rq, err := http.NewRequest(http.MethodGet, link, nil)
+	require.Nil(t, err)
+	if client.AuthToken != "" {
+		rq.Header.Set(model.HEADER_AUTH, client.AuthType+" "+client.AuthToken)
+	}
+
+	httpClient := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := httpClient.Do(rq)
+	require.Nil(t, err)
+	resp.Body.Close()
+
+	return resp
+}
+
+func TestFollowShortPermalink(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.ServiceSettings.SiteURL = th.Client.Url
+		*cfg.ServiceSettings.EnableShortPermalinks = true
+	})
+
+	channel := th.CreatePrivateChannel()
+	post := th.CreatePostWithClient(th.Client, channel)
+
+	// The same link is put in the notifications about the post
+	link, err := th.App.GetShortPermalink(post, th.BasicTeam.Id)
+	require.Nil(t, err)
+	require.True(t, strings.HasPrefix(link, th.Client.Url+model.SHORT_PERMALINK_PATH))
+
+	t.Run("authorized", func(t *testing.T) {
+		resp := followTestShortPermalink(t, th.Client, link)
+		require.Equal(t, http.StatusFound, resp.StatusCode)
+		assert.Equal(t, th.Client.Url+"/"+th.BasicTeam.Name+"/pl/"+post.Id, resp.Header.Get("Location"))
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		// BasicUser2 is on the team but not in the private channel
+		client := th.CreateClient()
+		th.LoginBasic2WithClient(client)
+
+		resp := followTestShortPermalink(t, client, link)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		assert.NotContains(t, resp.Header.Get("Location"), post.Id)
+	})
+
+	t.Run("logged out", func(t *testing.T) {
+		resp := followTestShortPermalink(t, th.CreateClient(), link)
+		require.Equal(t, http.StatusFound, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("Location"), "/login?redirect_to=")
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		result := <-th.App.Srv.Store.ShortPermalink().Save(&model.ShortPermalink{PostId: post.Id, TeamId: th.BasicTeam.Id, ExpireAt: model.GetMillis() + 50})
+		require.Nil(t, result.Err)
+		time.Sleep(100 * time.Millisecond)
+
+		resp := followTestShortPermalink(t, th.Client, th.Client.Url+model.SHORT_PERMALINK_PATH+result.Data.(*model.ShortPermalink).Code)
+		assert.Equal(t, http.StatusGone, resp.StatusCode)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		resp := followTestShortPermalink(t, th.Client, th.Client.Url+model.SHORT_PERMALINK_PATH+model.NewRandomString(model.SHORT_PERMALINK_CODE_LENGTH))
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableShortPermalinks = false })
+		defer th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableShortPermalinks = true })
+
+		resp := followTestShortPermalink(t, th.Client, link)
+		assert.Equal(t, http.StatusNotImplemented, resp.StatusCode)
+	})
+}
@@ -9,8 +9,10 @@ import (
 	"io"
 	"net/http"
 	"runtime"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/mattermost/mattermost-server/mlog"
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/utils"
 )
@@ -18,32 +20,71 @@ import (
 func (api *API) InitSystem() {
 	api.BaseRoutes.System.Handle("/ping", api.ApiHandler(getSystemPing)).Methods("GET")
 
+	api.BaseRoutes.System.Handle("/errors", api.ApiHandler(getAppErrorCatalog)).Methods("GET")
+
 	api.BaseRoutes.System.Handle("/timezones", api.ApiSessionRequired(getSupportedTimezones)).Methods("GET")
 
-	api.BaseRoutes.ApiRoot.Handle("/config", api.ApiSessionRequired(getConfig)).Methods("GET")
+	api.BaseRoutes.System.Handle("/announcement", api.ApiSessionRequired(setAnnouncement)).Methods("PUT")
+	api.BaseRoutes.System.Handle("/announcement", api.ApiSessionRequired(clearAnnouncement)).Methods("DELETE")
+
+	api.BaseRoutes.System.Handle("/maintenance", api.ApiSessionRequired(setMaintenanceMode)).Methods("PUT")
+
+	api.BaseRoutes.System.Handle("/drain", api.ApiPermissionRequired(model.PERMISSION_MANAGE_SYSTEM, startDrain)).Methods("POST")
+	api.BaseRoutes.System.Handle("/drain", api.ApiPermissionRequired(model.PERMISSION_MANAGE_SYSTEM, getDrainStatus)).Methods("GET")
+
+	api.BaseRoutes.System.Handle("/feature_flags", api.ApiPermissionRequired(model.PERMISSION_READ_SYSTEM_CONSOLE, getFeatureFlagOverrides)).Methods("GET")
+	api.BaseRoutes.System.Handle("/feature_flags", api.ApiSessionRequired(setFeatureFlagOverrides)).Methods("PUT")
+
+	api.BaseRoutes.System.Handle("/link_blocklist", api.ApiPermissionRequired(model.PERMISSION_READ_SYSTEM_CONSOLE, getBlockedDomains)).Methods("GET")
+	api.BaseRoutes.System.Handle("/link_blocklist", api.ApiSessionRequired(addBlockedDomains)).Methods("POST")
+	api.BaseRoutes.System.Handle("/link_blocklist/remove", api.ApiSessionRequired(removeBlockedDomains)).Methods("POST")
+
+	api.BaseRoutes.System.Handle("/traces", api.ApiPermissionRequired(model.PERMISSION_MANAGE_SYSTEM, getRecentRequestTraces)).Methods("GET")
+
+	api.BaseRoutes.System.Handle("/support_packet", api.ApiPermissionRequired(model.PERMISSION_MANAGE_SYSTEM, generateSupportPacket)).Methods("GET")
+
+	api.BaseRoutes.System.Handle("/post_action_signing_secret", api.ApiPermissionRequired(model.PERMISSION_MANAGE_SYSTEM, getPostActionSigningSecret)).Methods("GET")
+
+	api.BaseRoutes.ApiRoot.Handle("/config", api.ApiPermissionRequired(model.PERMISSION_READ_SYSTEM_CONSOLE, getConfig)).Methods("GET")
 	api.BaseRoutes.ApiRoot.Handle("/config", api.ApiSessionRequired(updateConfig)).Methods("PUT")
+	api.BaseRoutes.ApiRoot.Handle("/config", api.ApiSessionRequired(patchConfig)).Methods("PATCH")
 	api.BaseRoutes.ApiRoot.Handle("/config/reload", api.ApiSessionRequired(configReload)).Methods("POST")
 	api.BaseRoutes.ApiRoot.Handle("/config/client", api.ApiHandler(getClientConfig)).Methods("GET")
-	api.BaseRoutes.ApiRoot.Handle("/config/environment", api.ApiSessionRequired(getEnvironmentConfig)).Methods("GET")
+	api.BaseRoutes.ApiRoot.Handle("/config/environment", api.ApiPermissionRequired(model.PERMISSION_READ_SYSTEM_CONSOLE, getEnvironmentConfig)).Methods("GET")
+	api.BaseRoutes.ApiRoot.Handle("/config/history", api.ApiPermissionRequired(model.PERMISSION_READ_SYSTEM_CONSOLE, getConfigHistory)).Methods("GET")
+	api.BaseRoutes.ApiRoot.Handle("/config/history/{history_id:[A-Za-z0-9]+}/diff", api.ApiPermissionRequired(model.PERMISSION_READ_SYSTEM_CONSOLE, getConfigHistoryDiff)).Methods("GET")
+	api.BaseRoutes.ApiRoot.Handle("/config/history/{history_id:[A-Za-z0-9]+}/restore", api.ApiSessionRequired(restoreConfigHistory)).Methods("POST")
 
 	api.BaseRoutes.ApiRoot.Handle("/license", api.ApiSessionRequired(addLicense)).Methods("POST")
 	api.BaseRoutes.ApiRoot.Handle("/license", api.ApiSessionRequired(removeLicense)).Methods("DELETE")
 	api.BaseRoutes.ApiRoot.Handle("/license/client", api.ApiHandler(getClientLicense)).Methods("GET")
 
-	api.BaseRoutes.ApiRoot.Handle("/audits", api.ApiSessionRequired(getAudits)).Methods("GET")
+	api.BaseRoutes.ApiRoot.Handle("/audits", api.ApiPermissionRequired(model.PERMISSION_READ_SYSTEM_CONSOLE, getAudits)).Methods("GET")
 	api.BaseRoutes.ApiRoot.Handle("/email/test", api.ApiSessionRequired(testEmail)).Methods("POST")
+	api.BaseRoutes.ApiRoot.Handle("/email/bounces", api.ApiHandler(receiveEmailBounce)).Methods("POST")
 	api.BaseRoutes.ApiRoot.Handle("/file/s3_test", api.ApiSessionRequired(testS3)).Methods("POST")
 	api.BaseRoutes.ApiRoot.Handle("/database/recycle", api.ApiSessionRequired(databaseRecycle)).Methods("POST")
 	api.BaseRoutes.ApiRoot.Handle("/caches/invalidate", api.ApiSessionRequired(invalidateCaches)).Methods("POST")
 
-	api.BaseRoutes.ApiRoot.Handle("/logs", api.ApiSessionRequired(getLogs)).Methods("GET")
+	api.BaseRoutes.ApiRoot.Handle("/logs", api.ApiPermissionRequired(model.PERMISSION_READ_SYSTEM_CONSOLE, getLogs)).Methods("GET")
 	api.BaseRoutes.ApiRoot.Handle("/logs", api.ApiHandler(postLog)).Methods("POST")
 
-	api.BaseRoutes.ApiRoot.Handle("/analytics/old", api.ApiSessionRequired(getAnalytics)).Methods("GET")
+	api.BaseRoutes.ApiRoot.Handle("/analytics/old", api.ApiPermissionRequired(model.PERMISSION_READ_SYSTEM_CONSOLE, getAnalytics)).Methods("GET")
 }
 
 func getSystemPing(c *Context, w http.ResponseWriter, r *http.Request) {
 
+	// Load balancers stop sending traffic here while the server is being upgraded
+	if mode := c.App.GetMaintenanceMode(); mode != nil && !mode.ReadOnly {
+		rdata := map[string]string{}
+		rdata[model.STATUS] = model.STATUS_MAINTENANCE
+		rdata["message"] = mode.Message
+
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(model.MapToJson(rdata)))
+		return
+	}
+
 	actualGoroutines := runtime.NumGoroutine()
 	if *c.App.Config().ServiceSettings.GoroutineHealthThreshold <= 0 || actualGoroutines <= *c.App.Config().ServiceSettings.GoroutineHealthThreshold {
 		m := make(map[string]string)
@@ -62,7 +103,7 @@ func getSystemPing(c *Context, w http.ResponseWriter, r *http.Request) {
 		rdata := map[string]string{}
 		rdata["status"] = "unhealthy"
 
-		mlog.Warn(fmt.Sprintf("The number of running goroutines is over the health threshold %v of %v", actualGoroutines, *c.App.Config().ServiceSettings.GoroutineHealthThreshold))
+		c.Log.Warn(fmt.Sprintf("The number of running goroutines is over the health threshold %v of %v", actualGoroutines, *c.App.Config().ServiceSettings.GoroutineHealthThreshold))
 
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(model.MapToJson(rdata)))
@@ -89,14 +130,35 @@ func testEmail(c *Context, w http.ResponseWriter, r *http.Request) {
 	ReturnStatusOK(w)
 }
 
-func getConfig(c *Context, w http.ResponseWriter, r *http.Request) {
-	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
-		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+// receiveEmailBounce is called by the email provider, rather than by a user, when an email couldn't be delivered.
+func receiveEmailBounce(c *Context, w http.ResponseWriter, r *http.Request) {
+	if err := c.App.CheckEmailBounceSecret(r.Header.Get(model.HEADER_EMAIL_BOUNCE_SECRET)); err != nil {
+		c.Err = err
 		return
 	}
 
+	bounce := model.EmailBounceFromJson(r.Body)
+	if bounce == nil || bounce.Recipient == "" {
+		c.SetInvalidParam("recipient")
+		return
+	}
+
+	if err := c.App.HandleEmailBounce(bounce); err != nil {
+		c.Err = err
+		return
+	}
+
+	ReturnStatusOK(w)
+}
+
+func getConfig(c *Context, w http.ResponseWriter, r *http.Request) {
 	cfg := c.App.GetConfig()
 
+	// Only system admins can change the config, so everyone else has every secret hidden
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		cfg.SanitizeForExport()
+	}
+
 	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
 	w.Write([]byte(cfg.ToJson()))
 }
@@ -128,8 +190,11 @@ func updateConfig(c *Context, w http.ResponseWriter, r *http.Request) {
 	// Do not allow plugin uploads to be toggled through the API
 	cfg.PluginSettings.EnableUploads = c.App.GetConfig().PluginSettings.EnableUploads
 
-	err := c.App.SaveConfig(cfg, true)
-	if err != nil {
+	err := c.App.SaveConfigFromVersion(cfg, true, c.Session.UserId, model.CONFIGURATION_HISTORY_SOURCE_API)
+	if err != nil && err.StatusCode == http.StatusConflict {
+		writeConfigConflict(c, w, r, err)
+		return
+	} else if err != nil {
 		c.Err = err
 		return
 	}
@@ -142,12 +207,89 @@ func updateConfig(c *Context, w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(cfg.ToJson()))
 }
 
-func getAudits(c *Context, w http.ResponseWriter, r *http.Request) {
+// writeConfigConflict responds to a save of a config that was based on an older version with the error along with
+// the current config, so that the client can make its changes to that instead.
+func writeConfigConflict(c *Context, w http.ResponseWriter, r *http.Request, err *model.AppError) {
+	err.Translate(c.T)
+	err.RequestId = c.RequestId
+	err.Where = r.URL.Path
+	c.LogAudit("updateConfig conflict")
+
+	conflict := &model.ConfigConflict{AppError: *err, Config: c.App.GetConfig()}
+
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.WriteHeader(http.StatusConflict)
+	w.Write([]byte(conflict.ToJson()))
+}
+
+func patchConfig(c *Context, w http.ResponseWriter, r *http.Request) {
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	cfg, err := c.App.PatchConfig(r.Body, c.Session.UserId, model.CONFIGURATION_HISTORY_SOURCE_API)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("patchConfig")
+
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Write([]byte(cfg.ToJson()))
+}
+
+func getConfigHistory(c *Context, w http.ResponseWriter, r *http.Request) {
+	list, err := c.App.GetConfigHistoryPage(c.Params.Page, c.Params.PerPage)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Write([]byte(model.ConfigurationHistoryListToJson(list)))
+}
+
+func getConfigHistoryDiff(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireHistoryId()
+	if c.Err != nil {
+		return
+	}
+
+	changes, err := c.App.GetConfigHistoryDiff(c.Params.HistoryId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Write([]byte(model.ConfigChangesToJson(changes)))
+}
+
+func restoreConfigHistory(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireHistoryId()
+	if c.Err != nil {
+		return
+	}
+
 	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
 		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
 		return
 	}
 
+	if err := c.App.RestoreConfigHistory(c.Params.HistoryId, c.Session.UserId); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("history_id=" + c.Params.HistoryId)
+
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Write([]byte(c.App.GetConfig().ToJson()))
+}
+
+func getAudits(c *Context, w http.ResponseWriter, r *http.Request) {
 	audits, err := c.App.GetAuditsPage("", c.Params.Page, c.Params.PerPage)
 
 	if err != nil {
@@ -186,12 +328,196 @@ func invalidateCaches(c *Context, w http.ResponseWriter, r *http.Request) {
 	ReturnStatusOK(w)
 }
 
-func getLogs(c *Context, w http.ResponseWriter, r *http.Request) {
+func setAnnouncement(c *Context, w http.ResponseWriter, r *http.Request) {
+	announcement := model.AnnouncementFromJson(r.Body)
+	if announcement == nil {
+		c.SetInvalidParam("announcement")
+		return
+	}
+
 	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
 		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
 		return
 	}
 
+	if err := c.App.SetAnnouncement(announcement); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("")
+	ReturnStatusOK(w)
+}
+
+func clearAnnouncement(c *Context, w http.ResponseWriter, r *http.Request) {
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	if err := c.App.ClearAnnouncement(); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("")
+	ReturnStatusOK(w)
+}
+
+func setMaintenanceMode(c *Context, w http.ResponseWriter, r *http.Request) {
+	requested := model.MaintenanceModeFromJson(r.Body)
+	if requested == nil {
+		c.SetInvalidParam("maintenance_mode")
+		return
+	}
+
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	mode, err := c.App.SetMaintenanceMode(requested.Enabled, requested.ReadOnly, requested.Message)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit(fmt.Sprintf("enabled=%v read_only=%v", mode.Enabled, mode.ReadOnly))
+	w.Write([]byte(mode.ToJson()))
+}
+
+func startDrain(c *Context, w http.ResponseWriter, r *http.Request) {
+	status := c.App.StartDrain()
+
+	c.LogAudit(fmt.Sprintf("users=%v window_seconds=%v", status.Users, status.WindowSeconds))
+	w.Write([]byte(status.ToJson()))
+}
+
+func getDrainStatus(c *Context, w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(c.App.GetDrainStatus().ToJson()))
+}
+
+func getAppErrorCatalog(c *Context, w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(model.AppErrorCatalogToJson(model.GetAppErrorCatalog())))
+}
+
+func getRecentRequestTraces(c *Context, w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(model.RequestTraceListToJson(c.App.GetRecentRequestTraces())))
+}
+
+func getPostActionSigningSecret(c *Context, w http.ResponseWriter, r *http.Request) {
+	secret, err := c.App.GetPostActionSigningSecret(r.URL.Query().Get("url"))
+	if err != nil {
+		c.SetInvalidUrlParam("url")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Write([]byte(model.MapToJson(map[string]string{"secret": secret})))
+}
+
+func generateSupportPacket(c *Context, w http.ResponseWriter, r *http.Request) {
+	logMegabytes := model.SUPPORT_PACKET_DEFAULT_LOG_MEGABYTES
+	if value := r.URL.Query().Get("log_megabytes"); value != "" {
+		var convErr error
+		logMegabytes, convErr = strconv.Atoi(value)
+		if convErr != nil || logMegabytes < 0 || logMegabytes > model.SUPPORT_PACKET_MAX_LOG_MEGABYTES {
+			c.SetInvalidUrlParam("log_megabytes")
+			return
+		}
+	}
+
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", "attachment;filename=\"mattermost_support_packet_"+time.Now().Format("2006-01-02-15-04")+".zip\"")
+
+	// The packet is streamed as it's generated, so the headers are only sent once something has been written
+	if err := c.App.GenerateSupportPacket(w, int64(logMegabytes)*1024*1024); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Del("Content-Disposition")
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("generated support packet")
+}
+
+func getFeatureFlagOverrides(c *Context, w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(model.MapToJson(c.App.GetFeatureFlagOverrides())))
+}
+
+func setFeatureFlagOverrides(c *Context, w http.ResponseWriter, r *http.Request) {
+	overrides := model.MapFromJson(r.Body)
+
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	if err := c.App.SetFeatureFlagOverrides(overrides); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit(fmt.Sprintf("overrides=%v", model.MapToJson(overrides)))
+	w.Write([]byte(model.MapToJson(c.App.GetFeatureFlagOverrides())))
+}
+
+func getBlockedDomains(c *Context, w http.ResponseWriter, r *http.Request) {
+	domains, err := c.App.GetBlockedDomains()
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(model.BlockedDomainListToJson(domains)))
+}
+
+func addBlockedDomains(c *Context, w http.ResponseWriter, r *http.Request) {
+	domains := model.ArrayFromJson(r.Body)
+	if len(domains) == 0 {
+		c.SetInvalidParam("domains")
+		return
+	}
+
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	added, err := c.App.AddBlockedDomains(domains, c.Session.UserId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit(fmt.Sprintf("domains=%v", strings.Join(domains, ",")))
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(model.BlockedDomainListToJson(added)))
+}
+
+func removeBlockedDomains(c *Context, w http.ResponseWriter, r *http.Request) {
+	domains := model.ArrayFromJson(r.Body)
+	if len(domains) == 0 {
+		c.SetInvalidParam("domains")
+		return
+	}
+
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	if err := c.App.RemoveBlockedDomains(domains); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit(fmt.Sprintf("domains=%v", strings.Join(domains, ",")))
+	ReturnStatusOK(w)
+}
+
+func getLogs(c *Context, w http.ResponseWriter, r *http.Request) {
 	lines, err := c.App.GetLogs(c.Params.Page, c.Params.LogsPerPage)
 	if err != nil {
 		c.Err = err
@@ -230,7 +556,7 @@ func postLog(c *Context, w http.ResponseWriter, r *http.Request) {
 		err.Where = "client"
 		c.LogError(err)
 	} else {
-		mlog.Debug(fmt.Sprint(msg))
+		c.Log.Debug(fmt.Sprint(msg))
 	}
 
 	m["message"] = msg
@@ -254,11 +580,6 @@ func getClientConfig(c *Context, w http.ResponseWriter, r *http.Request) {
 }
 
 func getEnvironmentConfig(c *Context, w http.ResponseWriter, r *http.Request) {
-	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
-		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
-		return
-	}
-
 	envConfig := c.App.GetEnvironmentConfig()
 
 	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
@@ -375,11 +696,6 @@ func getAnalytics(c *Context, w http.ResponseWriter, r *http.Request) {
 		name = "standard"
 	}
 
-	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
-		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
-		return
-	}
-
 	rows, err := c.App.GetAnalytics(name, teamId)
 	if err != nil {
 		c.Err = err
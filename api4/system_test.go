@@ -1,15 +1,24 @@
 package api4
 
 import (
+	"archive/zip"
+	"bytes"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
 
 	"github.com/mattermost/mattermost-server/mlog"
 	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetPing(t *testing.T) {
@@ -36,6 +45,509 @@ func TestGetPing(t *testing.T) {
 	}
 }
 
+func TestMaintenanceMode(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	defer th.App.SetMaintenanceMode(false, false, "")
+
+	th.App.UpdateConfig(func(cfg *model.Config) { cfg.ServiceSettings.EnableIncomingWebhooks = true })
+	hook, appErr := th.App.CreateIncomingWebhookForChannel(th.BasicUser.Id, th.BasicChannel, &model.IncomingWebhook{ChannelId: th.BasicChannel.Id})
+	require.Nil(t, appErr)
+
+	postToHook := func() int {
+		resp, err := http.Post(Client.Url+"/hooks/"+hook.Id, "application/json", strings.NewReader(`{"text": "test text"}`))
+		require.Nil(t, err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	WebSocketClient, appErr := th.CreateWebSocketClient()
+	require.Nil(t, appErr)
+	defer WebSocketClient.Close()
+
+	WebSocketClient.Listen()
+	require.Equal(t, model.STATUS_OK, (<-WebSocketClient.ResponseChannel).Status, "should have responded OK to authentication challenge")
+
+	t.Run("only system admins can turn it on", func(t *testing.T) {
+		_, resp := Client.SetMaintenanceMode(&model.MaintenanceMode{Enabled: true})
+		CheckForbiddenStatus(t, resp)
+	})
+
+	mode, resp := th.SystemAdminClient.SetMaintenanceMode(&model.MaintenanceMode{Enabled: true, Message: "Upgrading"})
+	CheckNoError(t, resp)
+	require.NotNil(t, mode)
+	assert.True(t, mode.Enabled)
+	assert.Equal(t, "Upgrading", mode.Message)
+
+	t.Run("websocket clients are told and disconnected", func(t *testing.T) {
+		timeout := time.After(5 * time.Second)
+		received := false
+		for {
+			select {
+			case event, ok := <-WebSocketClient.EventChannel:
+				if !ok {
+					assert.True(t, received, "should have received the maintenance mode event before being disconnected")
+					require.NotNil(t, WebSocketClient.ListenError)
+					assert.Contains(t, WebSocketClient.ListenError.DetailedError, "maintenance")
+					return
+				}
+
+				if event.Event == model.WEBSOCKET_EVENT_MAINTENANCE_MODE {
+					received = true
+					assert.Equal(t, true, event.Data["enabled"])
+					assert.Equal(t, "Upgrading", event.Data["message"])
+				}
+			case <-timeout:
+				require.Fail(t, "timed out waiting to be disconnected")
+			}
+		}
+	})
+
+	t.Run("requests are gated", func(t *testing.T) {
+		_, resp := Client.GetMe("")
+		require.NotNil(t, resp.Error)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, "app.maintenance_mode.enabled_with_message.app_error", resp.Error.Id)
+
+		_, resp = th.SystemAdminClient.GetMe("")
+		CheckNoError(t, resp)
+
+		assert.Equal(t, http.StatusServiceUnavailable, postToHook())
+
+		status, resp := Client.GetPing()
+		assert.Equal(t, model.STATUS_MAINTENANCE, status)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+		// System admins can still log in to turn it back off
+		adminClient := th.CreateClient()
+		_, resp = adminClient.Login(th.SystemAdminUser.Email, th.SystemAdminUser.Password)
+		CheckNoError(t, resp)
+	})
+
+	t.Run("new websocket connections are refused", func(t *testing.T) {
+		wsClient, appErr := th.CreateWebSocketClient()
+		require.Nil(t, appErr)
+		defer wsClient.Close()
+
+		wsClient.Listen()
+
+		_, ok := <-wsClient.ResponseChannel
+		assert.False(t, ok, "shouldn't have been authenticated")
+	})
+
+	mode, resp = th.SystemAdminClient.SetMaintenanceMode(&model.MaintenanceMode{Enabled: false})
+	CheckNoError(t, resp)
+	assert.False(t, mode.Enabled)
+
+	t.Run("requests are allowed once it's turned off", func(t *testing.T) {
+		th.LoginBasic()
+
+		_, resp := Client.GetMe("")
+		CheckNoError(t, resp)
+
+		assert.Equal(t, http.StatusOK, postToHook())
+
+		status, resp := Client.GetPing()
+		CheckNoError(t, resp)
+		assert.Equal(t, model.STATUS_OK, status)
+	})
+}
+
+func TestReadOnlyMaintenanceMode(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	defer th.App.SetMaintenanceMode(false, false, "")
+
+	WebSocketClient, appErr := th.CreateWebSocketClient()
+	require.Nil(t, appErr)
+	defer WebSocketClient.Close()
+
+	WebSocketClient.Listen()
+	require.Equal(t, model.STATUS_OK, (<-WebSocketClient.ResponseChannel).Status, "should have responded OK to authentication challenge")
+
+	mode, resp := th.SystemAdminClient.SetMaintenanceMode(&model.MaintenanceMode{Enabled: true, ReadOnly: true})
+	CheckNoError(t, resp)
+	assert.True(t, mode.ReadOnly)
+
+	t.Run("websocket clients stay connected", func(t *testing.T) {
+		timeout := time.After(5 * time.Second)
+		for received := false; !received; {
+			select {
+			case event, ok := <-WebSocketClient.EventChannel:
+				require.True(t, ok, "shouldn't have been disconnected")
+				if event.Event == model.WEBSOCKET_EVENT_MAINTENANCE_MODE {
+					received = true
+					assert.Equal(t, true, event.Data["read_only"])
+				}
+			case <-timeout:
+				require.Fail(t, "timed out waiting for the maintenance mode event")
+			}
+		}
+
+		WebSocketClient.GetStatuses()
+		response, ok := <-WebSocketClient.ResponseChannel
+		require.True(t, ok)
+		assert.Equal(t, model.STATUS_OK, response.Status)
+	})
+
+	t.Run("reads succeed", func(t *testing.T) {
+		_, resp := Client.GetMe("")
+		CheckNoError(t, resp)
+
+		_, resp = Client.GetPostsForChannel(th.BasicChannel.Id, 0, 10, "")
+		CheckNoError(t, resp)
+
+		status, resp := Client.GetPing()
+		CheckNoError(t, resp)
+		assert.Equal(t, model.STATUS_OK, status)
+
+		config, resp := Client.GetOldClientConfig("")
+		CheckNoError(t, resp)
+		assert.Equal(t, "true", config["DatabaseReadOnly"])
+	})
+
+	t.Run("writes fail", func(t *testing.T) {
+		_, resp := Client.CreatePost(&model.Post{ChannelId: th.BasicChannel.Id, Message: "queued"})
+		require.NotNil(t, resp.Error)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, store.READ_ONLY_ERROR, resp.Error.Id)
+
+		_, resp = Client.PatchUser(th.BasicUser.Id, &model.UserPatch{Nickname: model.NewString("changed")})
+		require.NotNil(t, resp.Error)
+		assert.Equal(t, store.READ_ONLY_ERROR, resp.Error.Id)
+
+		// Writes made while serving reads are refused by the store
+		_, err := th.App.CreatePost(&model.Post{ChannelId: th.BasicChannel.Id, UserId: th.BasicUser.Id, Message: "queued"}, th.BasicChannel, false)
+		require.NotNil(t, err)
+		assert.Equal(t, store.READ_ONLY_ERROR, err.Id)
+
+		result := <-th.App.Srv.Store.Preference().Save(&model.Preferences{{UserId: th.BasicUser.Id, Category: model.PREFERENCE_CATEGORY_DISPLAY_SETTINGS, Name: model.NewId(), Value: "value"}})
+		require.NotNil(t, result.Err)
+		assert.Equal(t, store.READ_ONLY_ERROR, result.Err.Id)
+	})
+
+	mode, resp = th.SystemAdminClient.SetMaintenanceMode(&model.MaintenanceMode{Enabled: false})
+	CheckNoError(t, resp)
+	assert.False(t, mode.Enabled)
+
+	t.Run("writes succeed once it's turned off", func(t *testing.T) {
+		_, resp := Client.CreatePost(&model.Post{ChannelId: th.BasicChannel.Id, Message: "sent"})
+		CheckNoError(t, resp)
+
+		config, resp := Client.GetOldClientConfig("")
+		CheckNoError(t, resp)
+		assert.Equal(t, "false", config["DatabaseReadOnly"])
+	})
+}
+
+func TestDrain(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.WebsocketDrainWindowSeconds = 1 })
+
+	WebSocketClient, appErr := th.CreateWebSocketClient()
+	require.Nil(t, appErr)
+	defer WebSocketClient.Close()
+
+	WebSocketClient.Listen()
+	require.Equal(t, model.STATUS_OK, (<-WebSocketClient.ResponseChannel).Status, "should have responded OK to authentication challenge")
+	require.Equal(t, model.WEBSOCKET_EVENT_HELLO, (<-WebSocketClient.EventChannel).Event)
+
+	t.Run("only system admins can drain the server", func(t *testing.T) {
+		_, resp := Client.StartDrain()
+		CheckForbiddenStatus(t, resp)
+
+		_, resp = Client.GetDrainStatus()
+		CheckForbiddenStatus(t, resp)
+	})
+
+	status, resp := th.SystemAdminClient.GetDrainStatus()
+	CheckNoError(t, resp)
+	assert.False(t, status.Draining)
+	assert.False(t, status.Done)
+
+	status, resp = th.SystemAdminClient.StartDrain()
+	CheckNoError(t, resp)
+	assert.True(t, status.Draining)
+	assert.Equal(t, 1, status.WindowSeconds)
+	assert.Equal(t, 1, status.Users)
+
+	t.Run("websocket clients are told to reconnect and disconnected", func(t *testing.T) {
+		timeout := time.After(5 * time.Second)
+		received := false
+		for {
+			select {
+			case event, ok := <-WebSocketClient.EventChannel:
+				if !ok {
+					assert.True(t, received, "should have received the reconnect event before being disconnected")
+					require.NotNil(t, WebSocketClient.ListenError)
+					assert.Contains(t, WebSocketClient.ListenError.DetailedError, "draining")
+					return
+				}
+
+				if event.Event == model.WEBSOCKET_EVENT_RECONNECT {
+					received = true
+				}
+			case <-timeout:
+				require.Fail(t, "timed out waiting to be disconnected")
+			}
+		}
+	})
+
+	t.Run("new websocket connections are refused", func(t *testing.T) {
+		url := fmt.Sprintf("ws://localhost:%v%v/websocket", th.App.Srv.ListenAddr.Port, model.API_URL_SUFFIX)
+		_, httpResp, err := websocket.DefaultDialer.Dial(url, nil)
+		require.Error(t, err)
+		require.NotNil(t, httpResp)
+		assert.Equal(t, http.StatusServiceUnavailable, httpResp.StatusCode)
+		assert.Equal(t, "true", httpResp.Header.Get(model.HEADER_DRAINING))
+
+		_, appErr := th.CreateWebSocketClient()
+		assert.NotNil(t, appErr)
+	})
+
+	t.Run("requests are still served", func(t *testing.T) {
+		_, resp := Client.GetMe("")
+		CheckNoError(t, resp)
+
+		status, resp := Client.GetPing()
+		CheckNoError(t, resp)
+		assert.Equal(t, model.STATUS_OK, status)
+	})
+
+	t.Run("the drain finishes", func(t *testing.T) {
+		for start := time.Now(); ; time.Sleep(50 * time.Millisecond) {
+			status, resp := th.SystemAdminClient.GetDrainStatus()
+			CheckNoError(t, resp)
+			if status.Done {
+				assert.Equal(t, 1, status.UsersNotified)
+				assert.True(t, status.JobsFinished)
+				break
+			}
+			require.True(t, time.Since(start) < 10*time.Second, "the drain didn't finish")
+		}
+
+		// Draining again carries on with the drain that's already finished
+		again, resp := th.SystemAdminClient.StartDrain()
+		CheckNoError(t, resp)
+		assert.Equal(t, status.StartedAt, again.StartedAt)
+		assert.True(t, again.Done)
+	})
+}
+
+func TestLinkBlocklist(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	domain := model.NewId() + ".com"
+
+	_, resp := Client.GetBlockedDomains()
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = Client.AddBlockedDomains([]string{domain})
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = Client.RemoveBlockedDomains([]string{domain})
+	CheckForbiddenStatus(t, resp)
+
+	added, resp := th.SystemAdminClient.AddBlockedDomains([]string{domain, "*." + strings.ToUpper(domain)})
+	CheckNoError(t, resp)
+	CheckCreatedStatus(t, resp)
+	require.Len(t, added, 2)
+	assert.Equal(t, domain, added[0].Domain)
+	assert.Equal(t, "*."+domain, added[1].Domain)
+	assert.Equal(t, th.SystemAdminUser.Id, added[0].CreatorId)
+
+	_, resp = th.SystemAdminClient.AddBlockedDomains([]string{})
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = th.SystemAdminClient.AddBlockedDomains([]string{"not a domain"})
+	CheckBadRequestStatus(t, resp)
+
+	domains, resp := th.SystemAdminClient.GetBlockedDomains()
+	CheckNoError(t, resp)
+	assert.Contains(t, domains, added[0])
+	assert.Contains(t, domains, added[1])
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.LinkBlocklistMode = model.LINK_BLOCKLIST_MODE_BLOCK })
+	defer th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.LinkBlocklistMode = model.LINK_BLOCKLIST_MODE_OFF })
+
+	_, resp = Client.CreatePost(&model.Post{ChannelId: th.BasicChannel.Id, Message: "https://www." + domain})
+	CheckBadRequestStatus(t, resp)
+
+	ok, resp := th.SystemAdminClient.RemoveBlockedDomains([]string{domain, "*." + domain})
+	CheckNoError(t, resp)
+	assert.True(t, ok)
+
+	_, resp = Client.CreatePost(&model.Post{ChannelId: th.BasicChannel.Id, Message: "https://www." + domain})
+	CheckNoError(t, resp)
+}
+
+func TestGetAppErrorCatalog(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+	Client := th.Client
+
+	Client.Logout()
+	catalog, resp := Client.GetAppErrorCatalog()
+	CheckNoError(t, resp)
+	require.NotEmpty(t, catalog)
+
+	var invalidUrlParam *model.AppErrorCatalogEntry
+	for _, entry := range catalog {
+		assert.NotEmpty(t, entry.Code)
+		assert.NotZero(t, entry.StatusCode)
+		if entry.Id == "api.context.invalid_url_param.app_error" {
+			invalidUrlParam = entry
+		}
+	}
+	require.NotNil(t, invalidUrlParam)
+	assert.Equal(t, "context.invalid_url_param", invalidUrlParam.Code)
+	assert.Equal(t, http.StatusBadRequest, invalidUrlParam.StatusCode)
+
+	t.Run("error responses", func(t *testing.T) {
+		th.LoginBasic()
+
+		_, resp := Client.GetUser("junk", "")
+		CheckBadRequestStatus(t, resp)
+		assert.Equal(t, "context.invalid_url_param", resp.Error.Code)
+		assert.NotEmpty(t, resp.Error.RequestId)
+		assert.Equal(t, resp.RequestId, resp.Error.RequestId)
+
+		// errors that haven't been registered yet still have a code for their status
+		_, resp = Client.GetPost(model.NewId(), "")
+		CheckNotFoundStatus(t, resp)
+		assert.Equal(t, "not_found", resp.Error.Code)
+		assert.Equal(t, resp.RequestId, resp.Error.RequestId)
+	})
+}
+
+func TestRequestTracing(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	findTrace := func(requestId string) *model.RequestTrace {
+		traces, resp := th.SystemAdminClient.GetRecentRequestTraces()
+		CheckNoError(t, resp)
+
+		for _, trace := range traces {
+			if trace.RequestId == requestId {
+				return trace
+			}
+		}
+		return nil
+	}
+
+	_, resp := Client.GetRecentRequestTraces()
+	CheckForbiddenStatus(t, resp)
+
+	// nothing is recorded while tracing is disabled
+	_, resp = Client.GetMe("")
+	CheckNoError(t, resp)
+	assert.Nil(t, findTrace(resp.RequestId))
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.ServiceSettings.EnableRequestTracing = true
+		*cfg.ServiceSettings.RequestTracingSampleRate = 1
+		*cfg.ServiceSettings.RequestTracingSlowThresholdMilliseconds = 0
+	})
+	defer th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableRequestTracing = false })
+
+	_, resp = Client.GetChannel(th.BasicChannel.Id, "")
+	CheckNoError(t, resp)
+
+	trace := findTrace(resp.RequestId)
+	require.NotNil(t, trace, "a sampled request should have been traced")
+	assert.Equal(t, "api4.getChannel", trace.Handler)
+	assert.Equal(t, th.BasicUser.Id, trace.UserId)
+	assert.True(t, trace.DurationMillis > 0)
+
+	counts, _ := trace.SpanTotals()
+	assert.True(t, counts[model.REQUEST_TRACE_SPAN_STORE] > 0, "the store calls made by the request should have been traced")
+	for _, span := range trace.Spans {
+		assert.NotEmpty(t, span.Name)
+	}
+
+	// only requests slower than the threshold are kept
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.RequestTracingSlowThresholdMilliseconds = 60000 })
+
+	_, resp = Client.GetChannel(th.BasicChannel.Id, "")
+	CheckNoError(t, resp)
+	assert.Nil(t, findTrace(resp.RequestId))
+}
+
+func TestDisabledAPIEndpoints(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		cfg.ServiceSettings.DisabledAPIEndpoints = []string{"POST /api/v4/users/search", "* /api/v4/system"}
+	})
+	defer th.App.UpdateConfig(func(cfg *model.Config) { cfg.ServiceSettings.DisabledAPIEndpoints = []string{} })
+
+	t.Run("disabled endpoints are refused", func(t *testing.T) {
+		_, resp := Client.SearchUsers(&model.UserSearch{Term: th.BasicUser.Username})
+		CheckForbiddenStatus(t, resp)
+		CheckErrorMessage(t, resp, "api.context.api_endpoint_disabled.app_error")
+
+		_, resp = th.SystemAdminClient.SearchUsers(&model.UserSearch{Term: th.BasicUser.Username})
+		CheckForbiddenStatus(t, resp)
+	})
+
+	t.Run("adjacent endpoints still work", func(t *testing.T) {
+		_, resp := Client.GetUsers(0, 10, "")
+		CheckNoError(t, resp)
+
+		_, resp = Client.AutocompleteUsers(th.BasicUser.Username, "")
+		CheckNoError(t, resp)
+	})
+
+	t.Run("ping and login can't be disabled", func(t *testing.T) {
+		_, resp := Client.GetPing()
+		CheckNoError(t, resp)
+
+		_, resp = th.CreateClient().Login(th.BasicUser.Email, th.BasicUser.Password)
+		CheckNoError(t, resp)
+
+		cfg, resp := th.SystemAdminClient.GetConfig()
+		CheckNoError(t, resp)
+		cfg.ServiceSettings.DisabledAPIEndpoints = []string{"POST /api/v4/users/login"}
+		_, resp = th.SystemAdminClient.UpdateConfig(cfg)
+		CheckBadRequestStatus(t, resp)
+		CheckErrorMessage(t, resp, "model.config.is_valid.disabled_api_endpoint_required.app_error")
+	})
+
+	t.Run("unknown endpoints are invalid", func(t *testing.T) {
+		cfg := th.App.Config().Clone()
+		cfg.ServiceSettings.DisabledAPIEndpoints = []string{"POST /api/v4/users/serach"}
+		err := th.App.SaveConfig(cfg, false)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.disabled_api_endpoints.unknown.app_error", err.Id)
+
+		cfg.ServiceSettings.DisabledAPIEndpoints = []string{"PUT /api/v4/users/search"}
+		err = th.App.SaveConfig(cfg, false)
+		require.NotNil(t, err, "the route only exists for other methods")
+
+		assert.Equal(t, []string{"POST /api/v4/users/search", "* /api/v4/system"}, th.App.Config().ServiceSettings.DisabledAPIEndpoints)
+	})
+
+	t.Run("changes apply without a restart", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) { cfg.ServiceSettings.DisabledAPIEndpoints = []string{} })
+
+		_, resp := Client.SearchUsers(&model.UserSearch{Term: th.BasicUser.Username})
+		CheckNoError(t, resp)
+	})
+}
+
 func TestGetConfig(t *testing.T) {
 	th := Setup().InitBasic().InitSystemAdmin()
 	defer th.TearDown()
@@ -161,6 +673,181 @@ func TestUpdateConfig(t *testing.T) {
 	})
 }
 
+func TestUpdateConfigConflict(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+
+	// two admins read the config at the same time
+	first, resp := th.SystemAdminClient.GetConfig()
+	CheckNoError(t, resp)
+	second, resp := th.SystemAdminClient.GetConfig()
+	CheckNoError(t, resp)
+	require.Equal(t, *first.ConfigVersion, *second.ConfigVersion)
+
+	first.TeamSettings.SiteName = "First"
+	saved, resp := th.SystemAdminClient.UpdateConfig(first)
+	CheckNoError(t, resp)
+	assert.Equal(t, *first.ConfigVersion+1, *saved.ConfigVersion)
+
+	// and the second one's save would undo the first's
+	second.TeamSettings.SiteName = "Second"
+	current, resp := th.SystemAdminClient.UpdateConfig(second)
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, "app.admin.save_config.version_conflict.app_error", resp.Error.Id)
+	require.NotNil(t, current, "the current config should be returned to merge onto")
+	assert.Equal(t, "First", current.TeamSettings.SiteName)
+	assert.Equal(t, *saved.ConfigVersion, *current.ConfigVersion)
+	assert.Equal(t, model.FAKE_SETTING, *current.SqlSettings.DataSource)
+	assert.Equal(t, "First", th.App.Config().TeamSettings.SiteName)
+
+	// which the second admin can make their change to
+	current.TeamSettings.SiteName = "Second"
+	_, resp = th.SystemAdminClient.UpdateConfig(current)
+	CheckNoError(t, resp)
+	assert.Equal(t, "Second", th.App.Config().TeamSettings.SiteName)
+
+	current.ConfigVersion = nil
+	_, resp = th.SystemAdminClient.UpdateConfig(current)
+	CheckBadRequestStatus(t, resp)
+}
+
+func TestPatchConfig(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+
+	siteName := th.App.Config().TeamSettings.SiteName
+	defer th.App.UpdateConfig(func(cfg *model.Config) { cfg.TeamSettings.SiteName = siteName })
+
+	_, resp := th.Client.PatchConfig(map[string]interface{}{"TeamSettings": map[string]interface{}{"SiteName": "Patched"}})
+	CheckForbiddenStatus(t, resp)
+
+	// another admin's save that's based on an older version of the config
+	stale, resp := th.SystemAdminClient.GetConfig()
+	CheckNoError(t, resp)
+	stale.TeamSettings.MaxUsersPerTeam = model.NewInt(42)
+
+	cfg, resp := th.SystemAdminClient.PatchConfig(map[string]interface{}{"TeamSettings": map[string]interface{}{"SiteName": "Patched"}})
+	CheckNoError(t, resp)
+	assert.Equal(t, "Patched", cfg.TeamSettings.SiteName)
+	assert.Equal(t, model.FAKE_SETTING, *cfg.SqlSettings.DataSource)
+
+	_, resp = th.SystemAdminClient.UpdateConfig(stale)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode, "a patch should be a new version of the config")
+
+	// patches don't need the version, so they don't touch the settings that they don't have
+	cfg, resp = th.SystemAdminClient.PatchConfig(map[string]interface{}{"TeamSettings": map[string]interface{}{"MaxUsersPerTeam": 42}})
+	CheckNoError(t, resp)
+	assert.Equal(t, 42, *cfg.TeamSettings.MaxUsersPerTeam)
+	assert.Equal(t, "Patched", cfg.TeamSettings.SiteName)
+
+	_, resp = th.SystemAdminClient.PatchConfig(map[string]interface{}{"TeamSettings": map[string]interface{}{"NotASetting": true}})
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = th.SystemAdminClient.PatchConfig(map[string]interface{}{"TeamSettings": map[string]interface{}{"MaxUsersPerTeam": 0}})
+	CheckBadRequestStatus(t, resp)
+	assert.Equal(t, 42, *th.App.Config().TeamSettings.MaxUsersPerTeam)
+}
+
+func TestConfigHistory(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	siteName := th.App.Config().TeamSettings.SiteName
+	smtpPassword := th.App.Config().EmailSettings.SMTPPassword
+	defer th.App.UpdateConfig(func(cfg *model.Config) {
+		cfg.TeamSettings.SiteName = siteName
+		cfg.EmailSettings.SMTPPassword = smtpPassword
+	})
+
+	cfg, resp := th.SystemAdminClient.GetConfig()
+	CheckNoError(t, resp)
+
+	cfg.TeamSettings.SiteName = "First"
+	cfg.EmailSettings.SMTPPassword = "first-password"
+	cfg, resp = th.SystemAdminClient.UpdateConfig(cfg)
+	CheckNoError(t, resp)
+
+	cfg.TeamSettings.SiteName = "Second"
+	cfg.EmailSettings.SMTPPassword = "second-password"
+	_, resp = th.SystemAdminClient.UpdateConfig(cfg)
+	CheckNoError(t, resp)
+
+	list, resp := th.SystemAdminClient.GetConfigHistory(0, 2)
+	CheckNoError(t, resp)
+	require.Len(t, list, 2)
+	second, first := list[0], list[1]
+	assert.Equal(t, th.SystemAdminUser.Id, second.UserId)
+	assert.Equal(t, model.CONFIGURATION_HISTORY_SOURCE_API, second.Source)
+	assert.Contains(t, second.Summary, "TeamSettings.SiteName")
+	assert.Contains(t, second.Summary, "EmailSettings.SMTPPassword")
+
+	stored, err := th.App.GetConfigHistory(second.Id)
+	require.Nil(t, err)
+	assert.NotContains(t, stored.Config, "second-password", "secrets should only be stored encrypted")
+	assert.NotContains(t, stored.Secrets, "second-password", "secrets should only be stored encrypted")
+
+	_, resp = Client.GetConfigHistory(0, 2)
+	CheckForbiddenStatus(t, resp)
+
+	t.Run("diff", func(t *testing.T) {
+		changes, resp := th.SystemAdminClient.GetConfigHistoryDiff(second.Id)
+		CheckNoError(t, resp)
+
+		changed := map[string]*model.ConfigChange{}
+		for _, change := range changes {
+			changed[change.Setting] = change
+		}
+		require.Contains(t, changed, "TeamSettings.SiteName")
+		assert.Equal(t, "First", changed["TeamSettings.SiteName"].Old)
+		assert.Equal(t, "Second", changed["TeamSettings.SiteName"].New)
+		require.Contains(t, changed, "EmailSettings.SMTPPassword")
+		assert.Equal(t, model.FAKE_SETTING, changed["EmailSettings.SMTPPassword"].New)
+
+		_, resp = th.SystemAdminClient.GetConfigHistoryDiff(model.NewId())
+		CheckNotFoundStatus(t, resp)
+
+		_, resp = th.SystemAdminClient.GetConfigHistoryDiff("junk")
+		CheckBadRequestStatus(t, resp)
+
+		_, resp = Client.GetConfigHistoryDiff(second.Id)
+		CheckForbiddenStatus(t, resp)
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		_, resp := Client.RestoreConfigHistory(first.Id)
+		CheckForbiddenStatus(t, resp)
+
+		restored, resp := th.SystemAdminClient.RestoreConfigHistory(first.Id)
+		CheckNoError(t, resp)
+		assert.Equal(t, "First", restored.TeamSettings.SiteName)
+		assert.Equal(t, "First", th.App.Config().TeamSettings.SiteName)
+		assert.Equal(t, "first-password", th.App.Config().EmailSettings.SMTPPassword)
+
+		list, resp := th.SystemAdminClient.GetConfigHistory(0, 1)
+		CheckNoError(t, resp)
+		require.Len(t, list, 1)
+		assert.Equal(t, model.CONFIGURATION_HISTORY_SOURCE_RESTORE, list[0].Source)
+		assert.Equal(t, th.SystemAdminUser.Id, list[0].UserId)
+		assert.Contains(t, list[0].Summary, "TeamSettings.SiteName")
+
+		_, resp = th.SystemAdminClient.RestoreConfigHistory(model.NewId())
+		CheckNotFoundStatus(t, resp)
+	})
+
+	t.Run("restore invalid", func(t *testing.T) {
+		invalid := th.App.Config().Clone()
+		*invalid.TeamSettings.MaxUsersPerTeam = 0
+		history, err := th.App.RecordConfigHistory(th.App.Config(), invalid, "", model.CONFIGURATION_HISTORY_SOURCE_SERVER)
+		require.Nil(t, err)
+
+		_, resp := th.SystemAdminClient.RestoreConfigHistory(history.Id)
+		CheckBadRequestStatus(t, resp)
+		assert.NotEqual(t, 0, *th.App.Config().TeamSettings.MaxUsersPerTeam, "an invalid version shouldn't be applied")
+	})
+}
+
 func TestGetEnvironmentConfig(t *testing.T) {
 	os.Setenv("MM_SERVICESETTINGS_SITEURL", "http://example.mattermost.com")
 	os.Setenv("MM_SERVICESETTINGS_ENABLECUSTOMEMOJI", "true")
@@ -639,3 +1326,248 @@ func TestSupportedTimezones(t *testing.T) {
 	CheckNoError(t, resp)
 	assert.Equal(t, supportedTimezonesFromConfig, supportedTimezones)
 }
+
+func waitForBannerText(t *testing.T, WebSocketClient *model.WebSocketClient, timeout time.Duration) (string, bool) {
+	deadline := time.After(timeout)
+	for {
+		select {
+		case event := <-WebSocketClient.EventChannel:
+			if event.Event != model.WEBSOCKET_EVENT_CONFIG_CHANGED {
+				// Ignore any other events
+				continue
+			}
+
+			config, ok := event.Data["config"].(map[string]interface{})
+			if !ok {
+				t.Fatal("config_changed event should contain the client config")
+			}
+			text, _ := config["BannerText"].(string)
+			enabled, _ := config["EnableBanner"].(string)
+			return text, enabled == "true"
+		case <-deadline:
+			t.Fatal("timed out waiting for config_changed event")
+			return "", false
+		}
+	}
+}
+
+func TestAnnouncement(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	defer th.App.ClearAnnouncement()
+
+	WebSocketClient, err := th.CreateWebSocketClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer WebSocketClient.Close()
+	WebSocketClient.Listen()
+
+	announcement := &model.Announcement{
+		Text:        "Maintenance tonight",
+		Color:       "#f2a93b",
+		Dismissable: true,
+	}
+
+	_, resp := Client.SetAnnouncement(announcement)
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = th.SystemAdminClient.SetAnnouncement(&model.Announcement{Color: "#f2a93b"})
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = th.SystemAdminClient.SetAnnouncement(&model.Announcement{Text: "Too late", ExpiresAt: model.GetMillis() - 1000})
+	CheckBadRequestStatus(t, resp)
+
+	ok, resp := th.SystemAdminClient.SetAnnouncement(announcement)
+	CheckNoError(t, resp)
+	assert.True(t, ok)
+
+	text, enabled := waitForBannerText(t, WebSocketClient, 2*time.Second)
+	assert.Equal(t, "Maintenance tonight", text)
+	assert.True(t, enabled)
+
+	config, resp := Client.GetOldClientConfig("")
+	CheckNoError(t, resp)
+	assert.Equal(t, "Maintenance tonight", config["BannerText"])
+	assert.Equal(t, "#f2a93b", config["BannerColor"])
+	assert.Equal(t, "true", config["AllowBannerDismissal"])
+
+	_, resp = Client.ClearAnnouncement()
+	CheckForbiddenStatus(t, resp)
+
+	ok, resp = th.SystemAdminClient.ClearAnnouncement()
+	CheckNoError(t, resp)
+	assert.True(t, ok)
+
+	text, _ = waitForBannerText(t, WebSocketClient, 2*time.Second)
+	assert.NotEqual(t, "Maintenance tonight", text)
+	assert.Nil(t, th.App.GetAnnouncement())
+
+	t.Run("expires", func(t *testing.T) {
+		_, resp := th.SystemAdminClient.SetAnnouncement(&model.Announcement{
+			Text:      "Short lived",
+			ExpiresAt: model.GetMillis() + 1000,
+		})
+		CheckNoError(t, resp)
+
+		text, _ := waitForBannerText(t, WebSocketClient, 2*time.Second)
+		assert.Equal(t, "Short lived", text)
+
+		text, _ = waitForBannerText(t, WebSocketClient, 5*time.Second)
+		assert.NotEqual(t, "Short lived", text)
+		assert.Nil(t, th.App.GetAnnouncement())
+
+		config, resp := Client.GetOldClientConfig("")
+		CheckNoError(t, resp)
+		assert.NotEqual(t, "Short lived", config["BannerText"])
+	})
+}
+
+func TestFeatureFlagOverrides(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	defer th.App.SetFeatureFlagOverrides(nil)
+
+	_, resp := Client.GetFeatureFlagOverrides()
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = Client.SetFeatureFlagOverrides(map[string]string{"TestBoolFeature": "true"})
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = th.SystemAdminClient.SetFeatureFlagOverrides(map[string]string{"NotAFeature": "true"})
+	CheckBadRequestStatus(t, resp)
+
+	overrides, resp := th.SystemAdminClient.SetFeatureFlagOverrides(map[string]string{"TestBoolFeature": "true"})
+	CheckNoError(t, resp)
+	assert.Equal(t, map[string]string{"TestBoolFeature": "true"}, overrides)
+
+	overrides, resp = th.SystemAdminClient.GetFeatureFlagOverrides()
+	CheckNoError(t, resp)
+	assert.Equal(t, map[string]string{"TestBoolFeature": "true"}, overrides)
+
+	config, resp := Client.GetOldClientConfig("")
+	CheckNoError(t, resp)
+	assert.Equal(t, "true", config["FeatureFlagTestBoolFeature"])
+	assert.Equal(t, "off", config["FeatureFlagTestFeature"])
+
+	overrides, resp = th.SystemAdminClient.SetFeatureFlagOverrides(map[string]string{})
+	CheckNoError(t, resp)
+	assert.Empty(t, overrides)
+
+	config, resp = Client.GetOldClientConfig("")
+	CheckNoError(t, resp)
+	assert.Equal(t, "false", config["FeatureFlagTestBoolFeature"])
+}
+
+func TestLocalMode(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	server := httptest.NewServer(th.App.Srv.LocalRouter)
+	defer server.Close()
+	LocalClient := model.NewAPIv4Client(server.URL)
+
+	_, resp := LocalClient.GetConfig()
+	CheckNoError(t, resp)
+
+	team, resp := LocalClient.CreateTeam(&model.Team{
+		Name:        GenerateTestTeamName(),
+		DisplayName: "Local Team",
+		Type:        model.TEAM_OPEN,
+	})
+	CheckNoError(t, resp)
+	CheckCreatedStatus(t, resp)
+
+	members, err := th.App.GetTeamMembers(team.Id, 0, 10)
+	require.Nil(t, err)
+	assert.Len(t, members, 0, "there's no user to make the team's admin")
+
+	channel, resp := LocalClient.CreateChannel(&model.Channel{
+		TeamId:      team.Id,
+		Name:        GenerateTestChannelName(),
+		DisplayName: "Local Channel",
+		Type:        model.CHANNEL_OPEN,
+	})
+	CheckNoError(t, resp)
+
+	_, resp = LocalClient.AddTeamMember(team.Id, th.BasicUser.Id)
+	CheckNoError(t, resp)
+	_, resp = LocalClient.AddChannelMember(channel.Id, th.BasicUser.Id)
+	CheckNoError(t, resp)
+
+	_, resp = LocalClient.DeleteChannel(channel.Id)
+	CheckNoError(t, resp)
+
+	// The regular API still needs a session
+	th.Client.Logout()
+	_, resp = th.Client.GetConfig()
+	CheckUnauthorizedStatus(t, resp)
+}
+
+func TestGetPostActionSigningSecret(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	_, resp := Client.GetPostActionSigningSecret("https://example.com/actions")
+	CheckForbiddenStatus(t, resp)
+
+	secret, resp := th.SystemAdminClient.GetPostActionSigningSecret("https://example.com/actions")
+	CheckNoError(t, resp)
+	expected, err := model.PostActionSigningSecretForURL(*th.App.Config().ServiceSettings.PostActionSigningSecret, "https://example.com/actions")
+	require.Nil(t, err)
+	assert.Equal(t, expected, secret)
+
+	other, resp := th.SystemAdminClient.GetPostActionSigningSecret("https://example.org/actions")
+	CheckNoError(t, resp)
+	assert.NotEqual(t, secret, other)
+
+	_, resp = th.SystemAdminClient.GetPostActionSigningSecret("/actions")
+	CheckBadRequestStatus(t, resp)
+}
+
+func TestGenerateSupportPacket(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		cfg.EmailSettings.SMTPPassword = "smtp-password-sentinel"
+	})
+
+	_, resp := Client.GenerateSupportPacket(model.SUPPORT_PACKET_DEFAULT_LOG_MEGABYTES)
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = th.SystemAdminClient.GenerateSupportPacket(model.SUPPORT_PACKET_MAX_LOG_MEGABYTES + 1)
+	CheckBadRequestStatus(t, resp)
+
+	packet, resp := th.SystemAdminClient.GenerateSupportPacket(1)
+	CheckNoError(t, resp)
+
+	reader, err := zip.NewReader(bytes.NewReader(packet), int64(len(packet)))
+	require.Nil(t, err)
+
+	files := map[string]string{}
+	for _, file := range reader.File {
+		fileReader, err := file.Open()
+		require.Nil(t, err)
+		content, err := ioutil.ReadAll(fileReader)
+		fileReader.Close()
+		require.Nil(t, err)
+
+		files[file.Name] = string(content)
+		assert.NotContains(t, files[file.Name], "smtp-password-sentinel", "%v shouldn't contain a secret", file.Name)
+	}
+
+	manifest := model.SupportPacketManifestFromJson(strings.NewReader(files[model.SUPPORT_PACKET_MANIFEST_FILE]))
+	require.NotNil(t, manifest)
+	assert.Equal(t, model.CurrentVersion, manifest.ServerVersion)
+	for _, name := range manifest.Files {
+		assert.Contains(t, files, name)
+	}
+	assert.Contains(t, manifest.Files, model.SUPPORT_PACKET_CONFIG_FILE)
+}
@@ -6,9 +6,11 @@ package api4
 import (
 	"bytes"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/mattermost/mattermost-server/model"
 )
@@ -29,6 +31,10 @@ func (api *API) InitTeam() {
 	api.BaseRoutes.Team.Handle("", api.ApiSessionRequired(deleteTeam)).Methods("DELETE")
 	api.BaseRoutes.Team.Handle("/patch", api.ApiSessionRequired(patchTeam)).Methods("PUT")
 	api.BaseRoutes.Team.Handle("/stats", api.ApiSessionRequired(getTeamStats)).Methods("GET")
+	api.BaseRoutes.Team.Handle("/default_channels", api.ApiSessionRequired(getTeamDefaultChannels)).Methods("GET")
+	api.BaseRoutes.Team.Handle("/default_channels", api.ApiSessionRequired(updateTeamDefaultChannels)).Methods("PUT")
+	api.BaseRoutes.Team.Handle("/filtered_words", api.ApiSessionRequired(getTeamFilteredWords)).Methods("GET")
+	api.BaseRoutes.Team.Handle("/filtered_words", api.ApiSessionRequired(updateTeamFilteredWords)).Methods("PUT")
 
 	api.BaseRoutes.Team.Handle("/image", api.ApiSessionRequiredTrustRequester(getTeamIcon)).Methods("GET")
 	api.BaseRoutes.Team.Handle("/image", api.ApiSessionRequired(setTeamIcon)).Methods("POST")
@@ -51,6 +57,14 @@ func (api *API) InitTeam() {
 
 	api.BaseRoutes.Team.Handle("/import", api.ApiSessionRequired(importTeam)).Methods("POST")
 	api.BaseRoutes.Team.Handle("/invite/email", api.ApiSessionRequired(inviteUsersToTeam)).Methods("POST")
+	api.BaseRoutes.Team.Handle("/invite-guests/email", api.ApiSessionRequired(inviteGuestsToChannels)).Methods("POST")
+	api.BaseRoutes.Team.Handle("/invites", api.ApiSessionRequired(sendTeamInvites)).Methods("POST")
+	api.BaseRoutes.Team.Handle("/invites", api.ApiSessionRequired(getTeamInvitations)).Methods("GET")
+	api.BaseRoutes.Team.Handle("/invites/{invitation_id:[A-Za-z0-9]+}/resend", api.ApiSessionRequired(resendTeamInvitation)).Methods("POST")
+	api.BaseRoutes.Team.Handle("/invite_links", api.ApiSessionRequired(createInviteLink)).Methods("POST")
+	api.BaseRoutes.Team.Handle("/invite_links", api.ApiSessionRequired(getInviteLinks)).Methods("GET")
+	api.BaseRoutes.Team.Handle("/invite_links/{link_id:[A-Za-z0-9]+}", api.ApiSessionRequired(revokeInviteLink)).Methods("DELETE")
+	api.BaseRoutes.Team.Handle("/invite_links/{link_id:[A-Za-z0-9]+}/redemptions", api.ApiSessionRequired(getInviteLinkRedemptions)).Methods("GET")
 	api.BaseRoutes.Teams.Handle("/invite/{invite_id:[A-Za-z0-9]+}", api.ApiHandler(getInviteInfo)).Methods("GET")
 }
 
@@ -66,7 +80,14 @@ func createTeam(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rteam, err := c.App.CreateTeamWithUser(team, c.Session.UserId)
+	var rteam *model.Team
+	var err *model.AppError
+	if c.IsLocal {
+		// There's no user to make the team's admin over the local mode socket
+		rteam, err = c.App.CreateTeam(team)
+	} else {
+		rteam, err = c.App.CreateTeamWithUser(team, c.Session.UserId)
+	}
 	if err != nil {
 		c.Err = err
 		return
@@ -379,12 +400,15 @@ func addTeamMember(c *Context, w http.ResponseWriter, r *http.Request) {
 func addUserToTeamFromInvite(c *Context, w http.ResponseWriter, r *http.Request) {
 	tokenId := r.URL.Query().Get("token")
 	inviteId := r.URL.Query().Get("invite_id")
+	linkId := r.URL.Query().Get("link_id")
 
 	var member *model.TeamMember
 	var err *model.AppError
 
 	if len(tokenId) > 0 {
 		member, err = c.App.AddTeamMemberByToken(c.Session.UserId, tokenId)
+	} else if len(linkId) > 0 {
+		member, err = c.App.AddTeamMemberByInviteLink(linkId, c.Session.UserId)
 	} else if len(inviteId) > 0 {
 		member, err = c.App.AddTeamMemberByInviteId(inviteId, c.Session.UserId)
 	} else {
@@ -511,6 +535,96 @@ func getTeamStats(c *Context, w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func getTeamDefaultChannels(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireTeamId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_VIEW_TEAM) {
+		c.SetPermissionError(model.PERMISSION_VIEW_TEAM)
+		return
+	}
+
+	if channelIds, err := c.App.GetTeamDefaultChannels(c.Params.TeamId); err != nil {
+		c.Err = err
+		return
+	} else {
+		w.Write([]byte(model.ArrayToJson(channelIds)))
+	}
+}
+
+func updateTeamDefaultChannels(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireTeamId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_MANAGE_TEAM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_TEAM)
+		return
+	}
+
+	// An empty list is valid and clears the team's default channels, so a body that can't be read mustn't be taken
+	// for one
+	var channelIds []string
+	if err := json.NewDecoder(r.Body).Decode(&channelIds); err != nil {
+		c.SetInvalidParam("channel_ids")
+		return
+	}
+
+	if channelIds, err := c.App.UpdateTeamDefaultChannels(c.Params.TeamId, channelIds); err != nil {
+		c.Err = err
+		return
+	} else {
+		c.LogAudit("channel_ids=" + strings.Join(channelIds, ","))
+		w.Write([]byte(model.ArrayToJson(channelIds)))
+	}
+}
+
+func getTeamFilteredWords(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireTeamId()
+	if c.Err != nil {
+		return
+	}
+
+	// The list is only shown to those who can change it so that members can't look up how to get around it
+	if !c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_MANAGE_TEAM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_TEAM)
+		return
+	}
+
+	if words, err := c.App.GetTeamFilteredWords(c.Params.TeamId); err != nil {
+		c.Err = err
+		return
+	} else {
+		w.Write([]byte(model.ArrayToJson(words)))
+	}
+}
+
+func updateTeamFilteredWords(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireTeamId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_MANAGE_TEAM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_TEAM)
+		return
+	}
+
+	// An empty list is valid and clears the team's filtered words
+	words := model.ArrayFromJson(r.Body)
+
+	if words, err := c.App.UpdateTeamFilteredWords(c.Params.TeamId, words); err != nil {
+		c.Err = err
+		return
+	} else {
+		c.LogAudit("words=" + strconv.Itoa(len(words)))
+		w.Write([]byte(model.ArrayToJson(words)))
+	}
+}
+
 func updateTeamMemberRoles(c *Context, w http.ResponseWriter, r *http.Request) {
 	c.RequireTeamId().RequireUserId()
 	if c.Err != nil {
@@ -711,6 +825,263 @@ func inviteUsersToTeam(c *Context, w http.ResponseWriter, r *http.Request) {
 	ReturnStatusOK(w)
 }
 
+func inviteGuestsToChannels(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireTeamId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_INVITE_GUEST) {
+		c.SetPermissionError(model.PERMISSION_INVITE_GUEST)
+		return
+	}
+
+	guestsInvite := model.GuestsInviteFromJson(r.Body)
+	if guestsInvite == nil {
+		c.SetInvalidParam("guests_invite")
+		return
+	}
+
+	if err := c.App.InviteGuestsToChannels(c.Params.TeamId, guestsInvite, c.Session.UserId); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("team_id=" + c.Params.TeamId)
+	ReturnStatusOK(w)
+}
+
+func sendTeamInvites(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireTeamId()
+	if c.Err != nil {
+		return
+	}
+
+	invites := model.InviteListFromJson(r.Body)
+	if len(invites) == 0 {
+		c.SetInvalidParam("invites")
+		return
+	}
+
+	for _, invite := range invites {
+		if invite == nil {
+			c.SetInvalidParam("invites")
+			return
+		}
+
+		if invite.Role == model.INVITE_ROLE_GUEST {
+			if !c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_INVITE_GUEST) {
+				c.SetPermissionError(model.PERMISSION_INVITE_GUEST)
+				return
+			}
+		} else if !c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_INVITE_USER) ||
+			!c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_ADD_USER_TO_TEAM) {
+			c.SetPermissionError(model.PERMISSION_INVITE_USER)
+			return
+		}
+	}
+
+	results, err := c.App.SendTeamInvites(c.Params.TeamId, invites, c.Session.UserId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("team_id=" + c.Params.TeamId)
+	w.Write([]byte(model.InviteResultListToJson(results)))
+}
+
+func getTeamInvitations(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireTeamId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_INVITE_USER) &&
+		!c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_INVITE_GUEST) {
+		c.SetPermissionError(model.PERMISSION_INVITE_USER)
+		return
+	}
+
+	invitations, err := c.App.GetTeamInvitations(c.Params.TeamId, c.Params.Page, c.Params.PerPage)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(model.TeamInvitationListToJson(invitations)))
+}
+
+func resendTeamInvitation(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireTeamId().RequireInvitationId()
+	if c.Err != nil {
+		return
+	}
+
+	invitation, err := c.App.GetTeamInvitation(c.Params.InvitationId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	if invitation.TeamId != c.Params.TeamId {
+		c.Err = model.NewAppError("resendTeamInvitation", "api.team.invitation.team_mismatch.app_error", nil, "id="+invitation.Id, http.StatusNotFound)
+		return
+	}
+
+	if invitation.Role == model.INVITE_ROLE_GUEST {
+		if !c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_INVITE_GUEST) {
+			c.SetPermissionError(model.PERMISSION_INVITE_GUEST)
+			return
+		}
+	} else if !c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_INVITE_USER) ||
+		!c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_ADD_USER_TO_TEAM) {
+		c.SetPermissionError(model.PERMISSION_INVITE_USER)
+		return
+	}
+
+	invitation, err = c.App.ResendTeamInvitation(invitation)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("team_id=" + c.Params.TeamId + " invitation_id=" + invitation.Id)
+	w.Write([]byte(invitation.ToJson()))
+}
+
+// requireInviteLinkPermission checks that the session can manage links that let users, or guests if
+// guestOnly is set, join the team.
+func requireInviteLinkPermission(c *Context, guestOnly bool) bool {
+	if guestOnly {
+		if !c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_INVITE_GUEST) {
+			c.SetPermissionError(model.PERMISSION_INVITE_GUEST)
+			return false
+		}
+	} else if !c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_INVITE_USER) ||
+		!c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_ADD_USER_TO_TEAM) {
+		c.SetPermissionError(model.PERMISSION_INVITE_USER)
+		return false
+	}
+
+	return true
+}
+
+// getTeamInviteLink returns the link in the request's URL, making sure that it belongs to the team in it.
+func getTeamInviteLink(c *Context) *model.InviteLink {
+	link, err := c.App.GetInviteLink(c.Params.LinkId)
+	if err != nil {
+		c.Err = err
+		return nil
+	}
+
+	if link.TeamId != c.Params.TeamId {
+		c.Err = model.NewAppError("getTeamInviteLink", "api.team.invite_link.team_mismatch.app_error", nil, "id="+link.Id, http.StatusNotFound)
+		return nil
+	}
+
+	return link
+}
+
+func createInviteLink(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireTeamId()
+	if c.Err != nil {
+		return
+	}
+
+	link := model.InviteLinkFromJson(r.Body)
+	if link == nil {
+		c.SetInvalidParam("invite_link")
+		return
+	}
+
+	if !requireInviteLinkPermission(c, link.GuestOnly) {
+		return
+	}
+
+	link.TeamId = c.Params.TeamId
+	link.CreatorId = c.Session.UserId
+
+	rlink, err := c.App.CreateInviteLink(link)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("team_id=" + rlink.TeamId + " link_id=" + rlink.Id)
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(rlink.ToJson()))
+}
+
+func getInviteLinks(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireTeamId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_INVITE_USER) &&
+		!c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_INVITE_GUEST) {
+		c.SetPermissionError(model.PERMISSION_INVITE_USER)
+		return
+	}
+
+	links, err := c.App.GetInviteLinksForTeam(c.Params.TeamId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(model.InviteLinkListToJson(links)))
+}
+
+func revokeInviteLink(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireTeamId().RequireLinkId()
+	if c.Err != nil {
+		return
+	}
+
+	link := getTeamInviteLink(c)
+	if c.Err != nil {
+		return
+	}
+
+	if !requireInviteLinkPermission(c, link.GuestOnly) {
+		return
+	}
+
+	if _, err := c.App.RevokeInviteLink(link.Id); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("team_id=" + link.TeamId + " link_id=" + link.Id)
+	ReturnStatusOK(w)
+}
+
+func getInviteLinkRedemptions(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireTeamId().RequireLinkId()
+	if c.Err != nil {
+		return
+	}
+
+	link := getTeamInviteLink(c)
+	if c.Err != nil {
+		return
+	}
+
+	if !requireInviteLinkPermission(c, link.GuestOnly) {
+		return
+	}
+
+	redemptions, err := c.App.GetInviteLinkRedemptions(link.Id)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(model.InviteLinkRedemptionListToJson(redemptions)))
+}
+
 func getInviteInfo(c *Context, w http.ResponseWriter, r *http.Request) {
 	c.RequireInviteId()
 	if c.Err != nil {
@@ -0,0 +1,215 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func (api *API) InitTeamBranding() {
+	api.BaseRoutes.Team.Handle("/branding", api.ApiSessionRequiredTrustRequester(getTeamBranding)).Methods("GET")
+	api.BaseRoutes.Team.Handle("/branding/patch", api.ApiSessionRequired(patchTeamBranding)).Methods("PUT")
+	api.BaseRoutes.Team.Handle("/branding/image", api.ApiSessionRequiredTrustRequester(getTeamBrandingImage)).Methods("GET")
+	api.BaseRoutes.Team.Handle("/branding/image", api.ApiSessionRequired(uploadTeamBrandingImage)).Methods("POST")
+	api.BaseRoutes.Team.Handle("/branding/image", api.ApiSessionRequired(removeTeamBrandingImage)).Methods("DELETE")
+}
+
+// sessionCanViewTeamBranding follows the same rules as viewing a team's icon, since the branding is shown to
+// people who aren't on the team yet.
+func sessionCanViewTeamBranding(c *Context) bool {
+	team, err := c.App.GetTeam(c.Params.TeamId)
+	if err != nil {
+		c.Err = err
+		return false
+	}
+
+	if !c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_VIEW_TEAM) &&
+		(team.Type != model.TEAM_OPEN || team.AllowOpenInvite) {
+		c.SetPermissionError(model.PERMISSION_VIEW_TEAM)
+		return false
+	}
+
+	return true
+}
+
+// getTeamBrandingToManage returns the team's branding if the session can change it, which only system admins
+// can do once it's been locked.
+func getTeamBrandingToManage(c *Context) *model.TeamBranding {
+	if !c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_MANAGE_TEAM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_TEAM)
+		return nil
+	}
+
+	branding, err := c.App.GetTeamBranding(c.Params.TeamId)
+	if err != nil {
+		c.Err = err
+		return nil
+	}
+
+	if branding.Locked && !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.Err = model.NewAppError("getTeamBrandingToManage", "api.team.branding.locked.app_error", nil, "team_id="+c.Params.TeamId, http.StatusForbidden)
+		return nil
+	}
+
+	return branding
+}
+
+func getTeamBranding(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireTeamId()
+	if c.Err != nil {
+		return
+	}
+
+	if !sessionCanViewTeamBranding(c) {
+		return
+	}
+
+	branding, err := c.App.GetTeamBranding(c.Params.TeamId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	if c.HandleEtag(branding.Etag(), "Get Team Branding", w, r) {
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set(model.HEADER_ETAG_SERVER, branding.Etag())
+	w.Write([]byte(branding.ToJson()))
+}
+
+func patchTeamBranding(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireTeamId()
+	if c.Err != nil {
+		return
+	}
+
+	patch := model.TeamBrandingPatchFromJson(r.Body)
+	if patch == nil {
+		c.SetInvalidParam("branding")
+		return
+	}
+
+	branding := getTeamBrandingToManage(c)
+	if branding == nil {
+		return
+	}
+
+	if patch.Locked != nil && *patch.Locked != branding.Locked && !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	branding, err := c.App.PatchTeamBranding(c.Params.TeamId, patch)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("")
+	w.Write([]byte(branding.ToJson()))
+}
+
+func getTeamBrandingImage(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireTeamId()
+	if c.Err != nil {
+		return
+	}
+
+	if !sessionCanViewTeamBranding(c) {
+		return
+	}
+
+	branding, err := c.App.GetTeamBranding(c.Params.TeamId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	// the site's brand image can change without the team knowing, so it isn't given an etag
+	etag := ""
+	if branding.LastImageUpdate != 0 {
+		etag = branding.ImageEtag()
+	}
+
+	if c.HandleEtag(etag, "Get Team Branding Image", w, r) {
+		return
+	}
+
+	img, err := c.App.GetTeamBrandingImage(branding)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	if etag != "" {
+		w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%v, public", 24*60*60)) // 24 hrs
+		w.Header().Set(model.HEADER_ETAG_SERVER, etag)
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	w.Write(img)
+}
+
+func uploadTeamBrandingImage(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireTeamId()
+	if c.Err != nil {
+		return
+	}
+
+	branding := getTeamBrandingToManage(c)
+	if branding == nil {
+		return
+	}
+
+	if r.ContentLength > model.TEAM_BRANDING_IMAGE_MAX_BYTES {
+		c.Err = model.NewAppError("uploadTeamBrandingImage", "app.team.branding.image.too_large.app_error", map[string]interface{}{"Max": model.TEAM_BRANDING_IMAGE_MAX_BYTES}, "", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if err := r.ParseMultipartForm(model.TEAM_BRANDING_IMAGE_MAX_BYTES); err != nil {
+		c.Err = model.NewAppError("uploadTeamBrandingImage", "api.team.branding.image.parse.app_error", nil, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	imageArray, ok := r.MultipartForm.File["image"]
+	if !ok || len(imageArray) == 0 {
+		c.Err = model.NewAppError("uploadTeamBrandingImage", "api.team.branding.image.no_file.app_error", nil, "", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.App.SetTeamBrandingImage(c.Params.TeamId, imageArray[0]); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("")
+	w.WriteHeader(http.StatusCreated)
+	ReturnStatusOK(w)
+}
+
+func removeTeamBrandingImage(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireTeamId()
+	if c.Err != nil {
+		return
+	}
+
+	branding := getTeamBrandingToManage(c)
+	if branding == nil {
+		return
+	}
+
+	if err := c.App.RemoveTeamBrandingImage(c.Params.TeamId); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("")
+	ReturnStatusOK(w)
+}
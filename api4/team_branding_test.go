@@ -0,0 +1,195 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestGetTeamBranding(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+	team := th.BasicTeam
+
+	branding, resp := Client.GetTeamBranding(team.Id, "")
+	CheckNoError(t, resp)
+	assert.Equal(t, team.Id, branding.TeamId)
+	assert.Equal(t, "", branding.Description)
+	assert.Equal(t, "", branding.WelcomeText)
+
+	th.LoginTeamAdmin()
+
+	description := "a description"
+	patched, resp := Client.PatchTeamBranding(team.Id, &model.TeamBrandingPatch{Description: &description})
+	CheckNoError(t, resp)
+
+	branding, resp = Client.GetTeamBranding(team.Id, "")
+	CheckNoError(t, resp)
+	assert.Equal(t, description, branding.Description)
+	assert.Equal(t, patched.Etag(), resp.Etag)
+
+	branding, resp = Client.GetTeamBranding(team.Id, resp.Etag)
+	CheckEtag(t, branding, resp)
+
+	welcomeText := "welcome"
+	_, resp = Client.PatchTeamBranding(team.Id, &model.TeamBrandingPatch{WelcomeText: &welcomeText})
+	CheckNoError(t, resp)
+
+	branding, resp = Client.GetTeamBranding(team.Id, patched.Etag())
+	CheckNoError(t, resp)
+	assert.Equal(t, welcomeText, branding.WelcomeText)
+
+	_, resp = Client.GetTeamBranding(model.NewId(), "")
+	CheckNotFoundStatus(t, resp)
+
+	Client.Logout()
+
+	_, resp = Client.GetTeamBranding(team.Id, "")
+	CheckUnauthorizedStatus(t, resp)
+}
+
+func TestPatchTeamBranding(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+	team := th.BasicTeam
+
+	description := "a description"
+	patch := &model.TeamBrandingPatch{Description: &description}
+
+	_, resp := Client.PatchTeamBranding(team.Id, patch)
+	CheckForbiddenStatus(t, resp)
+
+	th.LoginTeamAdmin()
+
+	branding, resp := Client.PatchTeamBranding(team.Id, patch)
+	CheckNoError(t, resp)
+	assert.Equal(t, description, branding.Description)
+	assert.NotEqual(t, int64(0), branding.UpdateAt)
+
+	tooLong := string(make([]rune, model.TEAM_BRANDING_DESCRIPTION_MAX_RUNES+1))
+	_, resp = Client.PatchTeamBranding(team.Id, &model.TeamBrandingPatch{Description: &tooLong})
+	CheckBadRequestStatus(t, resp)
+
+	t.Run("only system admins can lock branding", func(t *testing.T) {
+		locked := true
+		_, resp := Client.PatchTeamBranding(team.Id, &model.TeamBrandingPatch{Locked: &locked})
+		CheckForbiddenStatus(t, resp)
+
+		branding, resp := th.SystemAdminClient.PatchTeamBranding(team.Id, &model.TeamBrandingPatch{Locked: &locked})
+		CheckNoError(t, resp)
+		assert.True(t, branding.Locked)
+
+		_, resp = Client.PatchTeamBranding(team.Id, patch)
+		CheckForbiddenStatus(t, resp)
+		CheckErrorMessage(t, resp, "api.team.branding.locked.app_error")
+
+		data, err := readTestFile("test.png")
+		require.Nil(t, err)
+
+		_, resp = Client.SetTeamBrandingImage(team.Id, data)
+		CheckForbiddenStatus(t, resp)
+
+		locked = false
+		branding, resp = th.SystemAdminClient.PatchTeamBranding(team.Id, &model.TeamBrandingPatch{Locked: &locked})
+		CheckNoError(t, resp)
+		assert.False(t, branding.Locked)
+
+		_, resp = Client.PatchTeamBranding(team.Id, patch)
+		CheckNoError(t, resp)
+	})
+}
+
+func TestSetTeamBrandingImage(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+	team := th.BasicTeam
+
+	data, err := readTestFile("test.png")
+	require.Nil(t, err)
+
+	_, resp := Client.SetTeamBrandingImage(team.Id, data)
+	CheckForbiddenStatus(t, resp)
+
+	th.LoginTeamAdmin()
+
+	ok, resp := Client.SetTeamBrandingImage(team.Id, data)
+	CheckNoError(t, resp)
+	CheckCreatedStatus(t, resp)
+	assert.True(t, ok)
+
+	branding, appErr := th.App.GetTeamBranding(team.Id)
+	require.Nil(t, appErr)
+	assert.NotEqual(t, int64(0), branding.LastImageUpdate)
+
+	t.Run("not an image", func(t *testing.T) {
+		_, resp := Client.SetTeamBrandingImage(team.Id, []byte("this is not an image"))
+		CheckBadRequestStatus(t, resp)
+		CheckErrorMessage(t, resp, "app.team.branding.image.type.app_error")
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		large := append(bytes.Repeat([]byte{0}, model.TEAM_BRANDING_IMAGE_MAX_BYTES), data...)
+		_, resp := Client.SetTeamBrandingImage(team.Id, large)
+		require.NotNil(t, resp.Error)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	})
+
+	info := &model.FileInfo{Path: "teams/" + team.Id + "/branding/image.png"}
+	require.Nil(t, th.cleanupTestFile(info))
+}
+
+func TestGetTeamBrandingImage(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+	team := th.BasicTeam
+
+	t.Run("falls back to the site's brand image", func(t *testing.T) {
+		_, resp := Client.GetTeamBrandingImage(team.Id, "")
+		if resp.StatusCode == http.StatusNotImplemented {
+			// the site's brand image isn't available without an enterprise license
+			CheckNotImplementedStatus(t, resp)
+		} else {
+			CheckNoError(t, resp)
+			assert.Equal(t, "", resp.Etag)
+		}
+	})
+
+	data, err := readTestFile("test.png")
+	require.Nil(t, err)
+
+	th.LoginTeamAdmin()
+
+	_, resp := Client.SetTeamBrandingImage(team.Id, data)
+	CheckNoError(t, resp)
+
+	img, resp := Client.GetTeamBrandingImage(team.Id, "")
+	CheckNoError(t, resp)
+	assert.NotEmpty(t, img)
+	assert.NotEqual(t, "", resp.Etag)
+
+	_, resp = Client.GetTeamBrandingImage(team.Id, resp.Etag)
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	_, resp = Client.GetTeamBrandingImage(model.NewId(), "")
+	CheckNotFoundStatus(t, resp)
+
+	ok, resp := Client.RemoveTeamBrandingImage(team.Id)
+	CheckNoError(t, resp)
+	assert.True(t, ok)
+
+	branding, appErr := th.App.GetTeamBranding(team.Id)
+	require.Nil(t, appErr)
+	assert.Equal(t, int64(0), branding.LastImageUpdate)
+}
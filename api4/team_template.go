@@ -0,0 +1,166 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func (api *API) InitTeamTemplate() {
+	api.BaseRoutes.Teams.Handle("/from_template", api.ApiSessionRequired(createTeamFromTemplate)).Methods("POST")
+
+	api.BaseRoutes.TeamTemplates.Handle("", api.ApiSessionRequired(saveTeamTemplate)).Methods("POST")
+	api.BaseRoutes.TeamTemplates.Handle("", api.ApiSessionRequired(getTeamTemplates)).Methods("GET")
+	api.BaseRoutes.TeamTemplate.Handle("", api.ApiSessionRequired(getTeamTemplate)).Methods("GET")
+	api.BaseRoutes.TeamTemplate.Handle("", api.ApiSessionRequired(updateTeamTemplate)).Methods("PUT")
+	api.BaseRoutes.TeamTemplate.Handle("", api.ApiSessionRequired(deleteTeamTemplate)).Methods("DELETE")
+}
+
+func createTeamFromTemplate(c *Context, w http.ResponseWriter, r *http.Request) {
+	template := model.TeamTemplateFromJson(r.Body)
+	if template == nil {
+		c.SetInvalidParam("template")
+		return
+	}
+
+	templateId := r.URL.Query().Get("template_id")
+	if templateId != "" && !model.IsValidId(templateId) {
+		c.SetInvalidUrlParam("template_id")
+		return
+	}
+
+	update, _ := strconv.ParseBool(r.URL.Query().Get("update"))
+
+	// Templates add anyone as an admin of the team, so they're only for those who could do that on any team
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	var team *model.Team
+	var err *model.AppError
+	if templateId != "" {
+		team, err = c.App.CreateTeamFromSavedTemplate(templateId, template.Team, c.Session.UserId, update)
+	} else {
+		team, err = c.App.CreateTeamFromTemplate(template, c.Session.UserId, update)
+	}
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("team_id=" + team.Id)
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(team.ToJson()))
+}
+
+func saveTeamTemplate(c *Context, w http.ResponseWriter, r *http.Request) {
+	template := model.TeamTemplateFromJson(r.Body)
+	if template == nil {
+		c.SetInvalidParam("template")
+		return
+	}
+
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	template.CreatorId = c.Session.UserId
+
+	saved, err := c.App.SaveTeamTemplate(template)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("template_id=" + saved.Id)
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(saved.ToJson()))
+}
+
+func getTeamTemplates(c *Context, w http.ResponseWriter, r *http.Request) {
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	templates, err := c.App.GetTeamTemplates(c.Params.Page, c.Params.PerPage)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(model.TeamTemplateListToJson(templates)))
+}
+
+func getTeamTemplate(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireTemplateId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	template, err := c.App.GetTeamTemplate(c.Params.TemplateId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(template.ToJson()))
+}
+
+func updateTeamTemplate(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireTemplateId()
+	if c.Err != nil {
+		return
+	}
+
+	template := model.TeamTemplateFromJson(r.Body)
+	if template == nil || template.Id != c.Params.TemplateId {
+		c.SetInvalidParam("template")
+		return
+	}
+
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	updated, err := c.App.UpdateTeamTemplate(template)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("template_id=" + updated.Id)
+	w.Write([]byte(updated.ToJson()))
+}
+
+func deleteTeamTemplate(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireTemplateId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	if err := c.App.DeleteTeamTemplate(c.Params.TemplateId); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("template_id=" + c.Params.TemplateId)
+	ReturnStatusOK(w)
+}
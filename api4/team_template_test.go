@@ -0,0 +1,128 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestCreateTeamFromTemplate(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { cfg.ServiceSettings.EnableIncomingWebhooks = true })
+
+	template := &model.TeamTemplate{
+		Team:             &model.Team{Name: "project" + model.NewId(), DisplayName: "Project", Type: model.TEAM_OPEN},
+		Channels:         []*model.TeamTemplateChannel{{Name: "standup", DisplayName: "Standup", Type: model.CHANNEL_OPEN, Header: "Every day at 9"}},
+		DefaultChannels:  []string{"standup"},
+		AdminEmails:      []string{th.BasicUser.Email},
+		IncomingWebhooks: []*model.TeamTemplateIncomingWebhook{{ChannelName: "standup", DisplayName: "Reports"}},
+	}
+
+	_, resp := th.Client.CreateTeamFromTemplate(template, false)
+	CheckForbiddenStatus(t, resp)
+
+	team, resp := th.SystemAdminClient.CreateTeamFromTemplate(template, false)
+	CheckNoError(t, resp)
+	CheckCreatedStatus(t, resp)
+	assert.Equal(t, template.Team.Name, team.Name)
+
+	channel, resp := th.Client.GetChannelByName("standup", team.Id, "")
+	CheckNoError(t, resp)
+	assert.Equal(t, "Every day at 9", channel.Header)
+
+	hooks, resp := th.SystemAdminClient.GetIncomingWebhooksForTeam(team.Id, 0, 100, "")
+	CheckNoError(t, resp)
+	require.Len(t, hooks, 1)
+	assert.Equal(t, th.SystemAdminUser.Id, hooks[0].UserId)
+
+	_, resp = th.SystemAdminClient.CreateTeamFromTemplate(template, false)
+	CheckBadRequestStatus(t, resp)
+
+	template.Channels[0].Header = "Every day at 10"
+	updated, resp := th.SystemAdminClient.CreateTeamFromTemplate(template, true)
+	CheckNoError(t, resp)
+	assert.Equal(t, team.Id, updated.Id)
+
+	channel, resp = th.Client.GetChannelByName("standup", team.Id, "")
+	CheckNoError(t, resp)
+	assert.Equal(t, "Every day at 10", channel.Header)
+
+	hooks, resp = th.SystemAdminClient.GetIncomingWebhooksForTeam(team.Id, 0, 100, "")
+	CheckNoError(t, resp)
+	assert.Len(t, hooks, 1)
+}
+
+func TestTeamTemplates(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+
+	template := &model.TeamTemplate{
+		Name:     "Project " + model.NewId(),
+		Team:     &model.Team{Type: model.TEAM_INVITE},
+		Channels: []*model.TeamTemplateChannel{{Name: "standup", DisplayName: "Standup", Type: model.CHANNEL_OPEN}},
+	}
+
+	_, resp := th.Client.SaveTeamTemplate(template)
+	CheckForbiddenStatus(t, resp)
+
+	saved, resp := th.SystemAdminClient.SaveTeamTemplate(template)
+	CheckNoError(t, resp)
+	CheckCreatedStatus(t, resp)
+	assert.Equal(t, th.SystemAdminUser.Id, saved.CreatorId)
+	assert.Equal(t, template.Channels, saved.Channels)
+
+	_, resp = th.SystemAdminClient.SaveTeamTemplate(template)
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = th.Client.GetTeamTemplates(0, 100)
+	CheckForbiddenStatus(t, resp)
+
+	templates, resp := th.SystemAdminClient.GetTeamTemplates(0, 100)
+	CheckNoError(t, resp)
+	found := false
+	for _, listed := range templates {
+		if listed.Id == saved.Id {
+			found = true
+			assert.Equal(t, saved.Channels, listed.Channels)
+		}
+	}
+	assert.True(t, found)
+
+	saved.Description = "A team for each project"
+	updated, resp := th.SystemAdminClient.UpdateTeamTemplate(saved)
+	CheckNoError(t, resp)
+	assert.Equal(t, "A team for each project", updated.Description)
+
+	fetched, resp := th.SystemAdminClient.GetTeamTemplate(saved.Id)
+	CheckNoError(t, resp)
+	assert.Equal(t, "A team for each project", fetched.Description)
+
+	_, resp = th.Client.CreateTeamFromSavedTemplate(saved.Id, &model.Team{Name: "apollo" + model.NewId(), DisplayName: "Apollo"}, false)
+	CheckForbiddenStatus(t, resp)
+
+	team, resp := th.SystemAdminClient.CreateTeamFromSavedTemplate(saved.Id, &model.Team{Name: "apollo" + model.NewId(), DisplayName: "Apollo"}, false)
+	CheckNoError(t, resp)
+	assert.Equal(t, "Apollo", team.DisplayName)
+	assert.Equal(t, model.TEAM_INVITE, team.Type)
+
+	_, resp = th.SystemAdminClient.CreateTeamFromSavedTemplate(model.NewId(), &model.Team{Name: "apollo" + model.NewId(), DisplayName: "Apollo"}, false)
+	CheckNotFoundStatus(t, resp)
+
+	_, resp = th.Client.DeleteTeamTemplate(saved.Id)
+	CheckForbiddenStatus(t, resp)
+
+	ok, resp := th.SystemAdminClient.DeleteTeamTemplate(saved.Id)
+	CheckNoError(t, resp)
+	require.True(t, ok)
+
+	_, resp = th.SystemAdminClient.GetTeamTemplate(saved.Id)
+	CheckNotFoundStatus(t, resp)
+}
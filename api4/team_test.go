@@ -10,6 +10,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"encoding/base64"
 
@@ -1579,6 +1580,64 @@ func TestRemoveTeamMember(t *testing.T) {
 	CheckNoError(t, resp)
 }
 
+func TestUpdateTeamDefaultChannels(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	channel1 := th.CreatePublicChannel()
+	channel2 := th.CreatePublicChannel()
+	channel3 := th.CreatePublicChannel()
+
+	_, resp := Client.UpdateTeamDefaultChannels(th.BasicTeam.Id, []string{channel1.Id})
+	CheckForbiddenStatus(t, resp)
+
+	th.LoginTeamAdmin()
+
+	channelIds, resp := Client.UpdateTeamDefaultChannels(th.BasicTeam.Id, []string{channel1.Id, channel2.Id, channel3.Id})
+	CheckNoError(t, resp)
+	assert.Len(t, channelIds, 3)
+
+	_, resp = Client.UpdateTeamDefaultChannels(th.BasicTeam.Id, []string{th.BasicPrivateChannel.Id})
+	CheckBadRequestStatus(t, resp)
+
+	for _, body := range []string{"", "{\"channel_ids\": []}", "[\"" + channel1.Id + "\""} {
+		r, err := Client.DoApiPut(Client.GetTeamDefaultChannelsRoute(th.BasicTeam.Id), body)
+		require.NotNil(t, err, "a malformed body shouldn't clear the default channels")
+		assert.Equal(t, "api.context.invalid_body_param.app_error", err.Id)
+		assert.Equal(t, http.StatusBadRequest, r.StatusCode)
+	}
+
+	th.LoginBasic()
+
+	channelIds, resp = Client.GetTeamDefaultChannels(th.BasicTeam.Id, "")
+	CheckNoError(t, resp)
+	assert.Len(t, channelIds, 3)
+
+	user := th.CreateUser()
+	_, resp = th.SystemAdminClient.AddTeamMember(th.BasicTeam.Id, user.Id)
+	CheckNoError(t, resp)
+
+	for _, channel := range []*model.Channel{channel1, channel2, channel3} {
+		_, resp = th.SystemAdminClient.GetChannelMember(channel.Id, user.Id, "")
+		CheckNoError(t, resp)
+	}
+
+	channelIds, resp = th.SystemAdminClient.UpdateTeamDefaultChannels(th.BasicTeam.Id, []string{})
+	CheckNoError(t, resp)
+	assert.Len(t, channelIds, 0)
+
+	_, resp = th.SystemAdminClient.GetChannelMember(channel1.Id, user.Id, "")
+	CheckNoError(t, resp)
+
+	_, resp = th.SystemAdminClient.GetTeamDefaultChannels(model.NewId(), "")
+	require.NotNil(t, resp.Error)
+
+	Client.Logout()
+	_, resp = Client.GetTeamDefaultChannels(th.BasicTeam.Id, "")
+	CheckUnauthorizedStatus(t, resp)
+}
+
 func TestGetTeamStats(t *testing.T) {
 	th := Setup().InitBasic().InitSystemAdmin()
 	defer th.TearDown()
@@ -1847,6 +1906,200 @@ func TestImportTeam(t *testing.T) {
 	})
 }
 
+func TestSendTeamInvites(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+
+	email := th.GenerateTestEmail()
+	invites := []*model.Invite{
+		{Email: email, Role: model.INVITE_ROLE_MEMBER, Channels: []string{th.BasicChannel.Id}},
+		{Email: th.BasicUser2.Email, Role: model.INVITE_ROLE_MEMBER},
+	}
+
+	results, resp := th.SystemAdminClient.SendTeamInvites(th.BasicTeam.Id, invites)
+	CheckNoError(t, resp)
+	require.Len(t, results, 2)
+	assert.Equal(t, email, results[0].Email)
+	assert.Nil(t, results[0].Error)
+	if assert.NotNil(t, results[1].Error) {
+		assert.Equal(t, "api.team.invite_members.already.app_error", results[1].Error.Id)
+	}
+
+	_, resp = th.SystemAdminClient.SendTeamInvites(th.BasicTeam.Id, []*model.Invite{})
+	CheckBadRequestStatus(t, resp)
+
+	// only team admins can invite guests by default
+	_, resp = th.Client.SendTeamInvites(th.BasicTeam.Id, []*model.Invite{
+		{Email: th.GenerateTestEmail(), Role: model.INVITE_ROLE_GUEST, Channels: []string{th.BasicChannel.Id}},
+	})
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = th.Client.SendTeamInvites(model.NewId(), invites)
+	CheckForbiddenStatus(t, resp)
+}
+
+func TestTeamInvitations(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+
+	defaultRolePermissions := th.SaveDefaultRolePermissions()
+	defer func() {
+		th.RestoreDefaultRolePermissions(defaultRolePermissions)
+	}()
+	th.RemovePermissionFromRole(model.PERMISSION_INVITE_USER.Id, model.TEAM_USER_ROLE_ID)
+	th.RemovePermissionFromRole(model.PERMISSION_INVITE_GUEST.Id, model.TEAM_USER_ROLE_ID)
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.EmailSettings.QueuedEmailsPerMinute = 0
+		*cfg.EmailSettings.BounceWebhookSecret = "secret"
+	})
+
+	email := th.GenerateTestEmail()
+	results, resp := th.SystemAdminClient.SendTeamInvites(th.BasicTeam.Id, []*model.Invite{{Email: email, Role: model.INVITE_ROLE_MEMBER}})
+	CheckNoError(t, resp)
+	require.Len(t, results, 1)
+	require.NotEmpty(t, results[0].InvitationId)
+	invitationId := results[0].InvitationId
+
+	var invitations []*model.TeamInvitation
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		invitations, resp = th.SystemAdminClient.GetTeamInvitations(th.BasicTeam.Id, 0, 10)
+		CheckNoError(t, resp)
+		require.Len(t, invitations, 1)
+		if invitations[0].Status != model.TEAM_INVITATION_STATUS_QUEUED {
+			break
+		}
+		require.True(t, time.Since(start) < 5*time.Second, "the invitation should have been sent")
+	}
+	assert.Equal(t, invitationId, invitations[0].Id)
+	assert.Equal(t, email, invitations[0].Email)
+
+	// Whether the email could actually be sent depends on the SMTP server that the tests are run with
+	result := <-th.App.Srv.Store.TeamInvitation().UpdateStatus(invitationId, model.TEAM_INVITATION_STATUS_SENT, "", model.GetMillis())
+	require.Nil(t, result.Err)
+
+	_, resp = th.SystemAdminClient.ResendTeamInvitation(th.BasicTeam.Id, invitationId)
+	CheckBadRequestStatus(t, resp)
+
+	t.Run("bounces", func(t *testing.T) {
+		client := th.CreateClient()
+
+		_, resp := client.SendEmailBounce(&model.EmailBounce{Recipient: email}, "wrong")
+		CheckUnauthorizedStatus(t, resp)
+
+		_, resp = client.SendEmailBounce(&model.EmailBounce{}, "secret")
+		CheckBadRequestStatus(t, resp)
+
+		ok, resp := client.SendEmailBounce(&model.EmailBounce{Recipient: email, Reason: "Mailbox full"}, "secret")
+		CheckNoError(t, resp)
+		assert.True(t, ok)
+
+		invitations, resp := th.SystemAdminClient.GetTeamInvitations(th.BasicTeam.Id, 0, 10)
+		CheckNoError(t, resp)
+		require.Len(t, invitations, 1)
+		assert.Equal(t, model.TEAM_INVITATION_STATUS_FAILED, invitations[0].Status)
+		assert.Equal(t, "Mailbox full", invitations[0].Reason)
+
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.EmailSettings.BounceWebhookSecret = "" })
+		defer th.App.UpdateConfig(func(cfg *model.Config) { *cfg.EmailSettings.BounceWebhookSecret = "secret" })
+
+		_, resp = client.SendEmailBounce(&model.EmailBounce{Recipient: email}, "")
+		CheckNotImplementedStatus(t, resp)
+	})
+
+	t.Run("resend", func(t *testing.T) {
+		_, resp := th.Client.ResendTeamInvitation(th.BasicTeam.Id, invitationId)
+		CheckForbiddenStatus(t, resp)
+
+		otherTeam := th.CreateTeamWithClient(th.SystemAdminClient)
+		_, resp = th.SystemAdminClient.ResendTeamInvitation(otherTeam.Id, invitationId)
+		CheckNotFoundStatus(t, resp)
+
+		invitation, resp := th.SystemAdminClient.ResendTeamInvitation(th.BasicTeam.Id, invitationId)
+		CheckNoError(t, resp)
+		assert.Equal(t, invitationId, invitation.Id)
+		assert.Equal(t, model.TEAM_INVITATION_STATUS_QUEUED, invitation.Status)
+	})
+
+	_, resp = th.Client.GetTeamInvitations(th.BasicTeam.Id, 0, 10)
+	CheckForbiddenStatus(t, resp)
+}
+
+func TestInviteLinks(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	link, resp := th.SystemAdminClient.CreateInviteLink(th.BasicTeam.Id, &model.InviteLink{MaxUses: 1})
+	CheckNoError(t, resp)
+	CheckCreatedStatus(t, resp)
+	assert.Equal(t, th.BasicTeam.Id, link.TeamId)
+	assert.Equal(t, th.SystemAdminUser.Id, link.CreatorId)
+
+	_, resp = th.SystemAdminClient.CreateInviteLink(th.BasicTeam.Id, &model.InviteLink{MaxUses: -1})
+	CheckBadRequestStatus(t, resp)
+
+	// only team admins can create guest links by default
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.GuestAccountsSettings.Enable = true })
+	_, resp = Client.CreateInviteLink(th.BasicTeam.Id, &model.InviteLink{GuestOnly: true})
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = Client.CreateInviteLink(model.NewId(), &model.InviteLink{})
+	CheckForbiddenStatus(t, resp)
+
+	user := th.CreateUser()
+	Client.Login(user.Email, user.Password)
+
+	member, resp := Client.AddTeamMemberFromInviteLink(link.Id)
+	CheckNoError(t, resp)
+	assert.Equal(t, user.Id, member.UserId)
+
+	ruser, resp := Client.CreateUserWithInviteLink(&model.User{Email: th.GenerateTestEmail(), Username: GenerateTestUsername(), Password: "passwd1"}, link.Id)
+	CheckBadRequestStatus(t, resp)
+	assert.Nil(t, ruser)
+
+	links, resp := th.SystemAdminClient.GetInviteLinks(th.BasicTeam.Id)
+	CheckNoError(t, resp)
+	require.Len(t, links, 1)
+	assert.Equal(t, 1, links[0].Uses)
+
+	redemptions, resp := th.SystemAdminClient.GetInviteLinkRedemptions(th.BasicTeam.Id, link.Id)
+	CheckNoError(t, resp)
+	require.Len(t, redemptions, 1)
+	assert.Equal(t, user.Id, redemptions[0].UserId)
+
+	other, resp := th.SystemAdminClient.CreateInviteLink(th.BasicTeam.Id, &model.InviteLink{})
+	CheckNoError(t, resp)
+
+	_, resp = th.SystemAdminClient.RevokeInviteLink(th.CreateTeam().Id, other.Id)
+	CheckNotFoundStatus(t, resp)
+
+	ok, resp := th.SystemAdminClient.RevokeInviteLink(th.BasicTeam.Id, other.Id)
+	CheckNoError(t, resp)
+	assert.True(t, ok)
+
+	_, resp = Client.CreateUserWithInviteLink(&model.User{Email: th.GenerateTestEmail(), Username: GenerateTestUsername(), Password: "passwd1"}, other.Id)
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = Client.GetInviteLinks(model.NewId())
+	CheckForbiddenStatus(t, resp)
+}
+
+func TestDisabledInviteId(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+
+	team, resp := th.SystemAdminClient.PatchTeam(th.BasicTeam.Id, &model.TeamPatch{InviteIdDisabled: model.NewBool(true)})
+	CheckNoError(t, resp)
+	assert.True(t, team.InviteIdDisabled)
+
+	_, resp = th.Client.GetTeamInviteInfo(team.InviteId)
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = th.Client.CreateUserWithInviteId(&model.User{Email: th.GenerateTestEmail(), Username: GenerateTestUsername(), Password: "passwd1"}, team.InviteId)
+	CheckForbiddenStatus(t, resp)
+}
+
 func TestInviteUsersToTeam(t *testing.T) {
 	th := Setup().InitBasic().InitSystemAdmin()
 	defer th.TearDown()
@@ -2055,3 +2308,41 @@ func TestRemoveTeamIcon(t *testing.T) {
 	_, resp = Client.RemoveTeamIcon(team.Id)
 	CheckForbiddenStatus(t, resp)
 }
+
+func TestTeamFilteredWords(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	_, resp := Client.GetTeamFilteredWords(th.BasicTeam.Id)
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = Client.UpdateTeamFilteredWords(th.BasicTeam.Id, []string{"darn"})
+	CheckForbiddenStatus(t, resp)
+
+	th.UpdateUserToTeamAdmin(th.BasicUser, th.BasicTeam)
+	th.App.InvalidateAllCaches()
+	th.LoginBasic()
+
+	words, resp := Client.GetTeamFilteredWords(th.BasicTeam.Id)
+	CheckNoError(t, resp)
+	assert.Empty(t, words)
+
+	words, resp = Client.UpdateTeamFilteredWords(th.BasicTeam.Id, []string{"Heck", "darn", "heck"})
+	CheckNoError(t, resp)
+	assert.Equal(t, []string{"darn", "heck"}, words)
+
+	words, resp = Client.GetTeamFilteredWords(th.BasicTeam.Id)
+	CheckNoError(t, resp)
+	assert.Equal(t, []string{"darn", "heck"}, words)
+
+	_, resp = Client.UpdateTeamFilteredWords(th.BasicTeam.Id, []string{"not a word"})
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = th.SystemAdminClient.UpdateTeamFilteredWords(th.BasicTeam.Id, []string{})
+	CheckNoError(t, resp)
+
+	Client.Logout()
+	_, resp = Client.GetTeamFilteredWords(th.BasicTeam.Id)
+	CheckUnauthorizedStatus(t, resp)
+}
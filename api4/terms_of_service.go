@@ -0,0 +1,102 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func (api *API) InitTermsOfService() {
+	api.BaseRoutes.TermsOfService.Handle("", api.ApiSessionRequired(getLatestTermsOfService)).Methods("GET")
+	api.BaseRoutes.TermsOfService.Handle("", api.ApiSessionRequired(createTermsOfService)).Methods("POST")
+
+	api.BaseRoutes.User.Handle("/terms_of_service", api.ApiSessionRequired(getUserTermsOfService)).Methods("GET")
+	api.BaseRoutes.User.Handle("/terms_of_service", api.ApiSessionRequired(acceptTermsOfService)).Methods("POST")
+}
+
+func getLatestTermsOfService(c *Context, w http.ResponseWriter, r *http.Request) {
+	termsOfService, err := c.App.GetLatestTermsOfService()
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(termsOfService.ToJson()))
+}
+
+func createTermsOfService(c *Context, w http.ResponseWriter, r *http.Request) {
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	props := model.MapFromJson(r.Body)
+
+	text := props["text"]
+	if len(text) == 0 {
+		c.SetInvalidParam("text")
+		return
+	}
+
+	termsOfService, err := c.App.CreateTermsOfService(text, c.Session.UserId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("id=" + termsOfService.Id)
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(termsOfService.ToJson()))
+}
+
+func getUserTermsOfService(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionToUser(c.Session, c.Params.UserId) {
+		c.SetPermissionError(model.PERMISSION_EDIT_OTHER_USERS)
+		return
+	}
+
+	userTermsOfService, err := c.App.GetUserTermsOfService(c.Params.UserId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(userTermsOfService.ToJson()))
+}
+
+func acceptTermsOfService(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId()
+	if c.Err != nil {
+		return
+	}
+
+	// Only users themselves can accept the terms of service
+	if c.Session.UserId != c.Params.UserId {
+		c.SetPermissionError(model.PERMISSION_EDIT_OTHER_USERS)
+		return
+	}
+
+	props := model.MapFromJson(r.Body)
+
+	termsOfServiceId := props["terms_of_service_id"]
+	if len(termsOfServiceId) != 26 {
+		c.SetInvalidParam("terms_of_service_id")
+		return
+	}
+
+	if err := c.App.AcceptTermsOfService(c.Params.UserId, termsOfServiceId); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("terms_of_service_id=" + termsOfServiceId)
+	ReturnStatusOK(w)
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestCreateTermsOfService(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+
+	_, resp := th.Client.CreateTermsOfService("You must behave")
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = th.SystemAdminClient.CreateTermsOfService("")
+	CheckBadRequestStatus(t, resp)
+
+	terms, resp := th.SystemAdminClient.CreateTermsOfService("You must behave")
+	CheckNoError(t, resp)
+	CheckCreatedStatus(t, resp)
+	assert.Equal(t, "You must behave", terms.Text)
+	assert.Equal(t, th.SystemAdminUser.Id, terms.UserId)
+
+	latest, resp := th.Client.GetTermsOfService("")
+	CheckNoError(t, resp)
+	assert.Equal(t, terms.Id, latest.Id)
+
+	th.Client.Logout()
+	_, resp = th.Client.GetTermsOfService("")
+	CheckUnauthorizedStatus(t, resp)
+}
+
+func TestAcceptTermsOfService(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.SupportSettings.CustomTermsOfServiceEnabled = true })
+
+	terms, resp := th.SystemAdminClient.CreateTermsOfService("You must behave")
+	CheckNoError(t, resp)
+
+	_, resp = th.Client.Login(th.BasicUser.Email, th.BasicUser.Password)
+	CheckNoError(t, resp)
+	assert.Equal(t, "true", resp.Header.Get(model.HEADER_TERMS_OF_SERVICE_REQUIRED))
+
+	latest, resp := th.Client.GetTermsOfService("")
+	CheckNoError(t, resp)
+	assert.Equal(t, "true", resp.Header.Get(model.HEADER_TERMS_OF_SERVICE_REQUIRED))
+
+	_, resp = th.Client.AcceptTermsOfService(th.BasicUser2.Id, latest.Id)
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = th.Client.AcceptTermsOfService(th.BasicUser.Id, "junk")
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = th.Client.AcceptTermsOfService(th.BasicUser.Id, model.NewId())
+	CheckBadRequestStatus(t, resp)
+
+	ok, resp := th.Client.AcceptTermsOfService(th.BasicUser.Id, latest.Id)
+	CheckNoError(t, resp)
+	assert.True(t, ok)
+
+	accepted, resp := th.Client.GetUserTermsOfService(th.BasicUser.Id, "")
+	CheckNoError(t, resp)
+	assert.Equal(t, terms.Id, accepted.TermsOfServiceId)
+	assert.Empty(t, resp.Header.Get(model.HEADER_TERMS_OF_SERVICE_REQUIRED))
+
+	_, resp = th.Client.GetUserTermsOfService(th.BasicUser2.Id, "")
+	CheckForbiddenStatus(t, resp)
+
+	// Changing the text requires accepting it again
+	time.Sleep(2 * time.Millisecond)
+	updated, resp := th.SystemAdminClient.CreateTermsOfService("You must behave, really")
+	CheckNoError(t, resp)
+
+	_, resp = th.Client.GetMe("")
+	CheckNoError(t, resp)
+	assert.Equal(t, "true", resp.Header.Get(model.HEADER_TERMS_OF_SERVICE_REQUIRED))
+
+	_, resp = th.Client.AcceptTermsOfService(th.BasicUser.Id, updated.Id)
+	CheckNoError(t, resp)
+
+	_, resp = th.Client.GetMe("")
+	CheckNoError(t, resp)
+	require.Empty(t, resp.Header.Get(model.HEADER_TERMS_OF_SERVICE_REQUIRED))
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func (api *API) InitThread() {
+	api.BaseRoutes.ThreadsForUser.Handle("", api.ApiSessionRequired(getThreadsForUser)).Methods("GET")
+	api.BaseRoutes.ThreadForUser.Handle("", api.ApiSessionRequired(getThreadForUser)).Methods("GET")
+	api.BaseRoutes.ThreadForUser.Handle("/read/{timestamp:[0-9]+}", api.ApiSessionRequired(updateThreadReadForUser)).Methods("PUT")
+	api.BaseRoutes.ThreadForUser.Handle("/following", api.ApiSessionRequired(followThreadByUser)).Methods("PUT")
+	api.BaseRoutes.ThreadForUser.Handle("/following", api.ApiSessionRequired(unfollowThreadByUser)).Methods("DELETE")
+}
+
+// checkThreadPermissions makes sure that the session belongs to the user whose threads are being
+// accessed and, if a thread was given, that the user can read the channel that it's in.
+func checkThreadPermissions(c *Context) bool {
+	if !c.App.SessionHasPermissionToUser(c.Session, c.Params.UserId) {
+		c.SetPermissionError(model.PERMISSION_EDIT_OTHER_USERS)
+		return false
+	}
+
+	if !c.App.SessionHasPermissionToTeam(c.Session, c.Params.TeamId, model.PERMISSION_VIEW_TEAM) {
+		c.SetPermissionError(model.PERMISSION_VIEW_TEAM)
+		return false
+	}
+
+	if len(c.Params.ThreadId) > 0 && !c.App.SessionHasPermissionToChannelByPost(c.Session, c.Params.ThreadId, model.PERMISSION_READ_CHANNEL) {
+		c.SetPermissionError(model.PERMISSION_READ_CHANNEL)
+		return false
+	}
+
+	return true
+}
+
+func getThreadsForUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId().RequireTeamId()
+	if c.Err != nil {
+		return
+	}
+
+	if !checkThreadPermissions(c) {
+		return
+	}
+
+	threads, err := c.App.GetThreadsForUser(c.Params.UserId, c.Params.TeamId, c.Params.Page, c.Params.PerPage)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(threads.ToJson()))
+}
+
+func getThreadForUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId().RequireTeamId().RequireThreadId()
+	if c.Err != nil {
+		return
+	}
+
+	if !checkThreadPermissions(c) {
+		return
+	}
+
+	thread, err := c.App.GetThreadForUser(c.Params.UserId, c.Params.TeamId, c.Params.ThreadId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(thread.ToJson()))
+}
+
+func updateThreadReadForUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId().RequireTeamId().RequireThreadId().RequireTimestamp()
+	if c.Err != nil {
+		return
+	}
+
+	if !checkThreadPermissions(c) {
+		return
+	}
+
+	thread, err := c.App.UpdateThreadReadForUser(c.Params.UserId, c.Params.TeamId, c.Params.ThreadId, c.Params.Timestamp)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(thread.ToJson()))
+}
+
+func followThreadByUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	updateThreadFollowForUser(c, w, true)
+}
+
+func unfollowThreadByUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	updateThreadFollowForUser(c, w, false)
+}
+
+func updateThreadFollowForUser(c *Context, w http.ResponseWriter, following bool) {
+	c.RequireUserId().RequireTeamId().RequireThreadId()
+	if c.Err != nil {
+		return
+	}
+
+	if !checkThreadPermissions(c) {
+		return
+	}
+
+	if _, err := c.App.UpdateThreadFollowForUser(c.Params.UserId, c.Params.TeamId, c.Params.ThreadId, following); err != nil {
+		c.Err = err
+		return
+	}
+
+	ReturnStatusOK(w)
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestGetThreadsForUser(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	threads, resp := Client.GetUserThreads(th.BasicUser.Id, th.BasicTeam.Id, 0, 60)
+	CheckNoError(t, resp)
+	assert.Equal(t, int64(0), threads.Total)
+
+	root := th.BasicPost
+	reply, resp := Client.CreatePost(&model.Post{ChannelId: root.ChannelId, RootId: root.Id, Message: "reply"})
+	CheckNoError(t, resp)
+
+	threads, resp = Client.GetUserThreads(th.BasicUser.Id, th.BasicTeam.Id, 0, 60)
+	CheckNoError(t, resp)
+	assert.Equal(t, int64(1), threads.Total)
+	require.Len(t, threads.Threads, 1)
+	assert.Equal(t, root.Id, threads.Threads[0].PostId)
+	assert.Equal(t, int64(1), threads.Threads[0].ReplyCount)
+	assert.Equal(t, reply.CreateAt, threads.Threads[0].LastReplyAt)
+	require.NotNil(t, threads.Threads[0].Post)
+	assert.Equal(t, root.Message, threads.Threads[0].Post.Message)
+
+	_, resp = Client.GetUserThreads(th.BasicUser2.Id, th.BasicTeam.Id, 0, 60)
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = Client.GetUserThreads(th.BasicUser.Id, model.NewId(), 0, 60)
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = th.SystemAdminClient.GetUserThreads(th.BasicUser.Id, th.BasicTeam.Id, 0, 60)
+	CheckNoError(t, resp)
+
+	Client.Logout()
+	_, resp = Client.GetUserThreads(th.BasicUser.Id, th.BasicTeam.Id, 0, 60)
+	CheckUnauthorizedStatus(t, resp)
+}
+
+func TestUpdateThreadForUser(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+	Client := th.Client
+
+	root := th.BasicPost
+	reply, resp := Client.CreatePost(&model.Post{ChannelId: root.ChannelId, RootId: root.Id, Message: "reply"})
+	CheckNoError(t, resp)
+
+	thread, resp := Client.GetUserThread(th.BasicUser.Id, th.BasicTeam.Id, root.Id)
+	CheckNoError(t, resp)
+	assert.Equal(t, int64(0), thread.UnreadReplies)
+
+	time.Sleep(time.Millisecond)
+	_, resp = Client.CreatePost(&model.Post{ChannelId: root.ChannelId, RootId: root.Id, Message: "another reply"})
+	CheckNoError(t, resp)
+
+	thread, resp = Client.UpdateThreadReadForUser(th.BasicUser.Id, th.BasicTeam.Id, root.Id, reply.CreateAt)
+	CheckNoError(t, resp)
+	assert.Equal(t, reply.CreateAt, thread.LastViewedAt)
+
+	_, resp = Client.UpdateThreadReadForUser(th.BasicUser.Id, th.BasicTeam.Id, root.Id, 0)
+	CheckBadRequestStatus(t, resp)
+
+	ok, resp := Client.UpdateThreadFollowForUser(th.BasicUser.Id, th.BasicTeam.Id, root.Id, false)
+	CheckNoError(t, resp)
+	assert.True(t, ok)
+
+	threads, resp := Client.GetUserThreads(th.BasicUser.Id, th.BasicTeam.Id, 0, 60)
+	CheckNoError(t, resp)
+	assert.Equal(t, int64(0), threads.Total)
+
+	_, resp = Client.UpdateThreadFollowForUser(th.BasicUser.Id, th.BasicTeam.Id, root.Id, true)
+	CheckNoError(t, resp)
+
+	threads, resp = Client.GetUserThreads(th.BasicUser.Id, th.BasicTeam.Id, 0, 60)
+	CheckNoError(t, resp)
+	assert.Equal(t, int64(1), threads.Total)
+
+	_, resp = Client.GetUserThread(th.BasicUser.Id, th.BasicTeam.Id, model.NewId())
+	CheckForbiddenStatus(t, resp)
+
+	privateChannel := th.CreatePrivateChannel()
+	privatePost := th.CreatePostWithClient(Client, privateChannel)
+	_, resp = Client.CreatePost(&model.Post{ChannelId: privateChannel.Id, RootId: privatePost.Id, Message: "reply"})
+	CheckNoError(t, resp)
+
+	th.LoginBasic2()
+	_, resp = Client.GetUserThread(th.BasicUser2.Id, th.BasicTeam.Id, privatePost.Id)
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = Client.UpdateThreadFollowForUser(th.BasicUser2.Id, th.BasicTeam.Id, privatePost.Id, true)
+	CheckForbiddenStatus(t, resp)
+}
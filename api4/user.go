@@ -6,13 +6,16 @@ package api4
 import (
 	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/mattermost/mattermost-server/app"
-	"github.com/mattermost/mattermost-server/mlog"
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/store"
+	"github.com/mattermost/mattermost-server/utils"
 )
 
 func (api *API) InitUser() {
@@ -22,8 +25,11 @@ func (api *API) InitUser() {
 	api.BaseRoutes.Users.Handle("/usernames", api.ApiSessionRequired(getUsersByNames)).Methods("POST")
 	api.BaseRoutes.Users.Handle("/search", api.ApiSessionRequired(searchUsers)).Methods("POST")
 	api.BaseRoutes.Users.Handle("/autocomplete", api.ApiSessionRequired(autocompleteUsers)).Methods("GET")
+	api.BaseRoutes.Users.Handle("/stats", api.ApiSessionRequired(getUsersStats)).Methods("GET")
+	api.BaseRoutes.Users.Handle("/username_policy/violations", api.ApiPermissionRequired(model.PERMISSION_READ_SYSTEM_CONSOLE, getUsernamePolicyViolations)).Methods("GET")
 
 	api.BaseRoutes.User.Handle("", api.ApiSessionRequired(getUser)).Methods("GET")
+	api.BaseRoutes.User.Handle("/boot", api.ApiSessionRequired(getBootData)).Methods("GET")
 	api.BaseRoutes.User.Handle("/image", api.ApiSessionRequiredTrustRequester(getProfileImage)).Methods("GET")
 	api.BaseRoutes.User.Handle("/image", api.ApiSessionRequired(setProfileImage)).Methods("POST")
 	api.BaseRoutes.User.Handle("", api.ApiSessionRequired(updateUser)).Methods("PUT")
@@ -31,11 +37,17 @@ func (api *API) InitUser() {
 	api.BaseRoutes.User.Handle("", api.ApiSessionRequired(deleteUser)).Methods("DELETE")
 	api.BaseRoutes.User.Handle("/roles", api.ApiSessionRequired(updateUserRoles)).Methods("PUT")
 	api.BaseRoutes.User.Handle("/active", api.ApiSessionRequired(updateUserActive)).Methods("PUT")
+	api.BaseRoutes.User.Handle("/deactivate_schedule", api.ApiSessionRequired(scheduleUserDeactivation)).Methods("PUT")
+	api.BaseRoutes.User.Handle("/deactivate_schedule", api.ApiSessionRequired(cancelUserDeactivation)).Methods("DELETE")
+	api.BaseRoutes.User.Handle("/promote", api.ApiSessionRequired(promoteGuestToUser)).Methods("POST")
+	api.BaseRoutes.User.Handle("/demote", api.ApiSessionRequired(demoteUserToGuest)).Methods("POST")
 	api.BaseRoutes.User.Handle("/password", api.ApiSessionRequired(updatePassword)).Methods("PUT")
 	api.BaseRoutes.Users.Handle("/password/reset", api.ApiHandler(resetPassword)).Methods("POST")
 	api.BaseRoutes.Users.Handle("/password/reset/send", api.ApiHandler(sendPasswordReset)).Methods("POST")
 	api.BaseRoutes.Users.Handle("/email/verify", api.ApiHandler(verifyUserEmail)).Methods("POST")
 	api.BaseRoutes.Users.Handle("/email/verify/send", api.ApiHandler(sendVerificationEmail)).Methods("POST")
+	api.BaseRoutes.Users.Handle("/email/undo_change", api.ApiHandler(undoEmailChange)).Methods("POST")
+	api.BaseRoutes.Root.Handle(app.EMAIL_CHANGE_UNDO_PATH, api.ApiHandler(undoEmailChangeFromLink)).Methods("GET")
 
 	api.BaseRoutes.User.Handle("/auth", api.ApiSessionRequiredTrustRequester(updateUserAuth)).Methods("PUT")
 
@@ -53,9 +65,20 @@ func (api *API) InitUser() {
 	api.BaseRoutes.User.Handle("/sessions", api.ApiSessionRequired(getSessions)).Methods("GET")
 	api.BaseRoutes.User.Handle("/sessions/revoke", api.ApiSessionRequired(revokeSession)).Methods("POST")
 	api.BaseRoutes.User.Handle("/sessions/revoke/all", api.ApiSessionRequired(revokeAllSessionsForUser)).Methods("POST")
+	api.BaseRoutes.User.Handle("/sessions/impersonate", api.ApiSessionRequired(impersonateUser)).Methods("POST")
 	api.BaseRoutes.Users.Handle("/sessions/device", api.ApiSessionRequired(attachDeviceId)).Methods("PUT")
 	api.BaseRoutes.User.Handle("/audits", api.ApiSessionRequired(getUserAudits)).Methods("GET")
 
+	api.BaseRoutes.User.Handle("/notification_trace", api.ApiSessionRequired(getNotificationTraceStatus)).Methods("GET")
+	api.BaseRoutes.User.Handle("/notification_trace", api.ApiSessionRequired(setNotificationTrace)).Methods("PUT")
+	api.BaseRoutes.User.Handle("/notification_trace/{post_id:[A-Za-z0-9]+}", api.ApiSessionRequired(getNotificationTrace)).Methods("GET")
+
+	api.BaseRoutes.User.Handle("/typing", api.ApiSessionRequired(publishUserTyping)).Methods("POST")
+
+	api.BaseRoutes.User.Handle("/blocked", api.ApiSessionRequired(getBlockedUsers)).Methods("GET")
+	api.BaseRoutes.User.Handle("/blocked/{blocked_user_id:[A-Za-z0-9]+}", api.ApiSessionRequired(blockUser)).Methods("POST")
+	api.BaseRoutes.User.Handle("/blocked/{blocked_user_id:[A-Za-z0-9]+}", api.ApiSessionRequired(unblockUser)).Methods("DELETE")
+
 	api.BaseRoutes.User.Handle("/tokens", api.ApiSessionRequired(createUserAccessToken)).Methods("POST")
 	api.BaseRoutes.User.Handle("/tokens", api.ApiSessionRequired(getUserAccessTokensForUser)).Methods("GET")
 	api.BaseRoutes.Users.Handle("/tokens", api.ApiSessionRequired(getUserAccessTokens)).Methods("GET")
@@ -75,6 +98,7 @@ func createUser(c *Context, w http.ResponseWriter, r *http.Request) {
 
 	tokenId := r.URL.Query().Get("t")
 	inviteId := r.URL.Query().Get("iid")
+	linkId := r.URL.Query().Get("lid")
 
 	// No permission check required
 
@@ -82,9 +106,11 @@ func createUser(c *Context, w http.ResponseWriter, r *http.Request) {
 	var err *model.AppError
 	if len(tokenId) > 0 {
 		ruser, err = c.App.CreateUserWithToken(user, tokenId)
+	} else if len(linkId) > 0 {
+		ruser, err = c.App.CreateUserWithInviteLink(user, linkId)
 	} else if len(inviteId) > 0 {
 		ruser, err = c.App.CreateUserWithInviteId(user, inviteId)
-	} else if c.IsSystemAdmin() {
+	} else if c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_USERS) {
 		ruser, err = c.App.CreateUserAsAdmin(user)
 	} else {
 		ruser, err = c.App.CreateUserFromSignup(user)
@@ -105,7 +131,13 @@ func getUser(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// No permission check required
+	if canSee, err := c.App.SessionCanSeeUser(c.Session, c.Params.UserId); err != nil {
+		c.Err = err
+		return
+	} else if !canSee {
+		c.Err = model.NewAppError("getUser", "api.user.guest_restricted.app_error", nil, "", http.StatusForbidden)
+		return
+	}
 
 	var user *model.User
 	var err *model.AppError
@@ -125,6 +157,11 @@ func getUser(c *Context, w http.ResponseWriter, r *http.Request) {
 		} else {
 			c.App.SanitizeProfile(user, c.IsSystemAdmin())
 		}
+		if err := c.App.SetUserAttributesOnProfiles([]*model.User{user}, c.Session.UserId, c.IsSystemAdmin()); err != nil {
+			c.Err = err
+			return
+		}
+		c.App.SetBlockedFlags(c.Session.UserId, []*model.User{user})
 		c.App.UpdateLastActivityAtIfNeeded(c.Session)
 		w.Header().Set(model.HEADER_ETAG_SERVER, etag)
 		w.Write([]byte(user.ToJson()))
@@ -138,8 +175,6 @@ func getUserByUsername(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// No permission check required
-
 	var user *model.User
 	var err *model.AppError
 
@@ -148,12 +183,24 @@ func getUserByUsername(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if canSee, err := c.App.SessionCanSeeUser(c.Session, user.Id); err != nil {
+		c.Err = err
+		return
+	} else if !canSee {
+		c.Err = model.NewAppError("getUserByUsername", "api.user.guest_restricted.app_error", nil, "", http.StatusForbidden)
+		return
+	}
+
 	etag := user.Etag(c.App.Config().PrivacySettings.ShowFullName, c.App.Config().PrivacySettings.ShowEmailAddress)
 
 	if c.HandleEtag(etag, "Get User", w, r) {
 		return
 	} else {
 		c.App.SanitizeProfile(user, c.IsSystemAdmin())
+		if err := c.App.SetUserAttributesOnProfiles([]*model.User{user}, c.Session.UserId, c.IsSystemAdmin()); err != nil {
+			c.Err = err
+			return
+		}
 		w.Header().Set(model.HEADER_ETAG_SERVER, etag)
 		w.Write([]byte(user.ToJson()))
 		return
@@ -166,8 +213,6 @@ func getUserByEmail(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// No permission check required
-
 	var user *model.User
 	var err *model.AppError
 
@@ -176,18 +221,82 @@ func getUserByEmail(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if canSee, err := c.App.SessionCanSeeUser(c.Session, user.Id); err != nil {
+		c.Err = err
+		return
+	} else if !canSee {
+		c.Err = model.NewAppError("getUserByEmail", "api.user.guest_restricted.app_error", nil, "", http.StatusForbidden)
+		return
+	}
+
 	etag := user.Etag(c.App.Config().PrivacySettings.ShowFullName, c.App.Config().PrivacySettings.ShowEmailAddress)
 
 	if c.HandleEtag(etag, "Get User", w, r) {
 		return
 	} else {
 		c.App.SanitizeProfile(user, c.IsSystemAdmin())
+		if err := c.App.SetUserAttributesOnProfiles([]*model.User{user}, c.Session.UserId, c.IsSystemAdmin()); err != nil {
+			c.Err = err
+			return
+		}
 		w.Header().Set(model.HEADER_ETAG_SERVER, etag)
 		w.Write([]byte(user.ToJson()))
 		return
 	}
 }
 
+func getBootData(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId()
+	if c.Err != nil {
+		return
+	}
+
+	// Everything is loaded as seen by the current user, so nobody else's can be requested
+	if c.Params.UserId != c.Session.UserId {
+		c.SetPermissionError(model.PERMISSION_EDIT_OTHER_USERS)
+		return
+	}
+
+	start := time.Now()
+	boot, timings := c.App.GetBootData(c.Session)
+	timings["total"] = time.Since(start)
+
+	for _, err := range boot.Errors {
+		err.Translate(c.T)
+		err.RequestId = c.RequestId
+		c.LogError(err)
+
+		if !*c.App.Config().ServiceSettings.EnableDeveloper {
+			err.DetailedError = ""
+		}
+	}
+
+	if *c.App.Config().ServiceSettings.EnableDeveloper {
+		w.Header().Set(model.HEADER_SERVER_TIMING, formatServerTiming(timings))
+	}
+
+	c.App.UpdateLastActivityAtIfNeeded(c.Session)
+
+	w.Write([]byte(boot.ToJson()))
+}
+
+// formatServerTiming formats the time taken by each part of a request as a Server-Timing header so that it's shown
+// by the browser's developer tools.
+func formatServerTiming(timings map[string]time.Duration) string {
+	names := make([]string, 0, len(timings))
+	for name := range timings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	metrics := make([]string, 0, len(names))
+	for _, name := range names {
+		metrics = append(metrics, fmt.Sprintf("%s;dur=%.3f", name, float64(timings[name])/float64(time.Millisecond)))
+	}
+
+	return strings.Join(metrics, ", ")
+}
+
 func getProfileImage(c *Context, w http.ResponseWriter, r *http.Request) {
 	c.RequireUserId()
 	if c.Err != nil {
@@ -204,7 +313,7 @@ func getProfileImage(c *Context, w http.ResponseWriter, r *http.Request) {
 		}
 
 		user := users[0]
-		etag := strconv.FormatInt(user.LastPictureUpdate, 10)
+		etag := c.App.GetProfileImageEtag(user)
 		if c.HandleEtag(etag, "Get Profile Image", w, r) {
 			return
 		}
@@ -218,6 +327,10 @@ func getProfileImage(c *Context, w http.ResponseWriter, r *http.Request) {
 
 		if readFailed {
 			w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%v, public", 5*60)) // 5 mins
+		} else if user.LastPictureUpdate == 0 {
+			// A default image changes when the user's name does, so it's checked against the etag more often
+			w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%v, public", 5*60)) // 5 mins
+			w.Header().Set(model.HEADER_ETAG_SERVER, etag)
 		} else {
 			w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%v, public", 24*60*60)) // 24 hrs
 			w.Header().Set(model.HEADER_ETAG_SERVER, etag)
@@ -279,6 +392,11 @@ func setProfileImage(c *Context, w http.ResponseWriter, r *http.Request) {
 }
 
 func getUsers(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireAllowedFields(model.USER_SPARSE_FIELDS)
+	if c.Err != nil {
+		return
+	}
+
 	inTeamId := r.URL.Query().Get("in_team")
 	notInTeamId := r.URL.Query().Get("not_in_team")
 	inChannelId := r.URL.Query().Get("in_channel")
@@ -307,10 +425,61 @@ func getUsers(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	filter, invalidParam := userFilterFromQuery(r.URL.Query())
+	if invalidParam != "" {
+		c.SetInvalidUrlParam(invalidParam)
+		return
+	}
+
 	var profiles []*model.User
 	var err *model.AppError
 	etag := ""
 
+	// Filtering by role, authentication method or activity is only supported when listing all users or a team's users
+	if !filter.IsEmpty() {
+		for param, value := range map[string]string{"not_in_team": notInTeamId, "in_channel": inChannelId, "not_in_channel": notInChannelId, "without_team": withoutTeam, "sort": sort} {
+			if value != "" {
+				c.SetInvalidUrlParam(param)
+				return
+			}
+		}
+
+		if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_READ_SYSTEM_CONSOLE) && !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_USERS) {
+			c.SetPermissionError(model.PERMISSION_MANAGE_USERS)
+			return
+		}
+
+		filter.TeamId = inTeamId
+		if profiles, err = c.App.GetUsersWithFilterPage(filter, c.Params.Page, c.Params.PerPage, true); err != nil {
+			c.Err = err
+			return
+		}
+
+		w.Write([]byte(model.UserListToSparseJson(profiles, c.Params.Fields)))
+		return
+	}
+
+	// Guests can only list the users they share a channel with
+	if c.Session.IsGuest() && len(inChannelId) == 0 {
+		if len(notInChannelId) > 0 || len(notInTeamId) > 0 || sort != "" {
+			c.Err = model.NewAppError("getUsers", "api.user.guest_restricted.app_error", nil, "", http.StatusForbidden)
+			return
+		}
+
+		if len(inTeamId) > 0 && !c.App.SessionHasPermissionToTeam(c.Session, inTeamId, model.PERMISSION_VIEW_TEAM) {
+			c.SetPermissionError(model.PERMISSION_VIEW_TEAM)
+			return
+		}
+
+		if profiles, err = c.App.GetUsersInUserChannelsPage(c.Session.UserId, inTeamId, c.Params.Page, c.Params.PerPage, false); err != nil {
+			c.Err = err
+			return
+		}
+
+		w.Write([]byte(model.UserListToSparseJson(profiles, c.Params.Fields)))
+		return
+	}
+
 	if withoutTeamBool, _ := strconv.ParseBool(withoutTeam); withoutTeamBool {
 		// Use a special permission for now
 		if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_LIST_USERS_WITHOUT_TEAM) {
@@ -384,11 +553,83 @@ func getUsers(c *Context, w http.ResponseWriter, r *http.Request) {
 			w.Header().Set(model.HEADER_ETAG_SERVER, etag)
 		}
 		c.App.UpdateLastActivityAtIfNeeded(c.Session)
-		w.Write([]byte(model.UserListToJson(profiles)))
+		w.Write([]byte(model.UserListToSparseJson(profiles, c.Params.Fields)))
+	}
+}
+
+// userFilterFromQuery returns the filter given by the query's role, auth_service, active, created_after,
+// created_before, last_seen_after and last_seen_before parameters, or the name of the parameter that isn't valid.
+func userFilterFromQuery(query url.Values) (*model.UserFilter, string) {
+	filter := &model.UserFilter{
+		Role:        query.Get("role"),
+		AuthService: query.Get("auth_service"),
+	}
+
+	if active := query.Get("active"); active != "" {
+		activeBool, err := strconv.ParseBool(active)
+		if err != nil {
+			return nil, "active"
+		}
+		filter.Active = &activeBool
+	}
+
+	for param, value := range map[string]*int64{
+		"created_after":    &filter.CreatedAfter,
+		"created_before":   &filter.CreatedBefore,
+		"last_seen_after":  &filter.LastSeenAfter,
+		"last_seen_before": &filter.LastSeenBefore,
+	} {
+		if str := query.Get(param); str != "" {
+			millis, err := strconv.ParseInt(str, 10, 64)
+			if err != nil || millis < 0 {
+				return nil, param
+			}
+			*value = millis
+		}
+	}
+
+	return filter, ""
+}
+
+func getUsersStats(c *Context, w http.ResponseWriter, r *http.Request) {
+	filter, invalidParam := userFilterFromQuery(r.URL.Query())
+	if invalidParam != "" {
+		c.SetInvalidUrlParam(invalidParam)
+		return
+	}
+	filter.TeamId = r.URL.Query().Get("in_team")
+
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_READ_SYSTEM_CONSOLE) && !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_USERS) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_USERS)
+		return
+	}
+
+	count, err := c.App.GetUsersCountWithFilter(filter)
+	if err != nil {
+		c.Err = err
+		return
 	}
+
+	stats := &model.UsersStats{TotalUsersCount: count}
+	w.Write([]byte(stats.ToJson()))
+}
+
+func getUsernamePolicyViolations(c *Context, w http.ResponseWriter, r *http.Request) {
+	violations, err := c.App.GetUsernamePolicyViolations()
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(model.UsernamePolicyViolationListToJson(violations)))
 }
 
 func getUsersByIds(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireAllowedFields(model.USER_SPARSE_FIELDS)
+	if c.Err != nil {
+		return
+	}
+
 	userIds := model.ArrayFromJson(r.Body)
 
 	if len(userIds) == 0 {
@@ -396,14 +637,21 @@ func getUsersByIds(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// No permission check required
+	// No permission check required, but guests only see the users they share a channel with
 
-	if users, err := c.App.GetUsersByIds(userIds, c.IsSystemAdmin()); err != nil {
+	users, err := c.App.GetUsersByIds(userIds, c.IsSystemAdmin())
+	if err == nil && c.Session.IsGuest() {
+		users, err = c.App.RestrictUsersToUserChannels(c.Session.UserId, users)
+	}
+
+	if err != nil {
 		c.Err = err
 		return
-	} else {
-		w.Write([]byte(model.UserListToJson(users)))
 	}
+
+	c.App.SetBlockedFlags(c.Session.UserId, users)
+
+	w.Write([]byte(model.UserListToSparseJson(users, c.Params.Fields)))
 }
 
 func getUsersByNames(c *Context, w http.ResponseWriter, r *http.Request) {
@@ -414,14 +662,19 @@ func getUsersByNames(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// No permission check required
+	// No permission check required, but guests only see the users they share a channel with
+
+	users, err := c.App.GetUsersByUsernames(usernames, c.IsSystemAdmin())
+	if err == nil && c.Session.IsGuest() {
+		users, err = c.App.RestrictUsersToUserChannels(c.Session.UserId, users)
+	}
 
-	if users, err := c.App.GetUsersByUsernames(usernames, c.IsSystemAdmin()); err != nil {
+	if err != nil {
 		c.Err = err
 		return
-	} else {
-		w.Write([]byte(model.UserListToJson(users)))
 	}
+
+	w.Write([]byte(model.UserListToJson(users)))
 }
 
 func searchUsers(c *Context, w http.ResponseWriter, r *http.Request) {
@@ -477,9 +730,29 @@ func searchUsers(c *Context, w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if c.Session.IsGuest() && props.InChannelId == "" {
+		if props.NotInChannelId != "" || props.NotInTeamId != "" || props.WithoutTeam {
+			c.Err = model.NewAppError("searchUsers", "api.user.guest_restricted.app_error", nil, "", http.StatusForbidden)
+			return
+		}
+
+		searchOptions[store.USER_SEARCH_OPTION_ALLOW_INACTIVE] = false
+		if profiles, err := c.App.SearchUsersInUserChannels(c.Session.UserId, props.TeamId, props.Term, searchOptions, false); err != nil {
+			c.Err = err
+		} else if profiles, err = c.App.FilterUsersByAttributes(profiles, props.Attributes, c.Session.UserId, false); err != nil {
+			c.Err = err
+		} else {
+			w.Write([]byte(model.UserListToJson(profiles)))
+		}
+		return
+	}
+
 	if profiles, err := c.App.SearchUsers(props, searchOptions, c.IsSystemAdmin()); err != nil {
 		c.Err = err
 		return
+	} else if profiles, err = c.App.FilterUsersByAttributes(profiles, props.Attributes, c.Session.UserId, c.IsSystemAdmin()); err != nil {
+		c.Err = err
+		return
 	} else {
 		w.Write([]byte(model.UserListToJson(profiles)))
 	}
@@ -502,6 +775,15 @@ func autocompleteUsers(c *Context, w http.ResponseWriter, r *http.Request) {
 		searchOptions[store.USER_SEARCH_OPTION_NAMES_ONLY] = true
 	}
 
+	// Deactivated users are left out unless they're asked for, but guests can never see them
+	if allowInactive, _ := strconv.ParseBool(r.URL.Query().Get("allow_inactive")); allowInactive && !c.Session.IsGuest() {
+		searchOptions[store.USER_SEARCH_OPTION_ALLOW_INACTIVE] = true
+	}
+
+	if excludeBots, _ := strconv.ParseBool(r.URL.Query().Get("exclude_bots")); excludeBots {
+		searchOptions[store.USER_SEARCH_OPTION_EXCLUDE_BOTS] = true
+	}
+
 	if len(channelId) > 0 {
 		if !c.App.SessionHasPermissionToChannel(c.Session, channelId, model.PERMISSION_READ_CHANNEL) {
 			c.SetPermissionError(model.PERMISSION_READ_CHANNEL)
@@ -515,20 +797,54 @@ func autocompleteUsers(c *Context, w http.ResponseWriter, r *http.Request) {
 		}
 
 		autocomplete.Users = result.InChannel
-		autocomplete.OutOfChannel = result.OutOfChannel
+		if !c.Session.IsGuest() {
+			autocomplete.OutOfChannel = result.OutOfChannel
+		}
+
+		channel, err := c.App.GetChannel(channelId)
+		if err != nil {
+			c.Err = err
+			return
+		}
+
+		if len(name) > 0 && !channel.DisableGroupMentions {
+			if autocomplete.Groups, err = c.App.AutocompleteCustomGroups(name); err != nil {
+				c.Err = err
+				return
+			}
+		}
 	} else if len(teamId) > 0 {
 		if !c.App.SessionHasPermissionToTeam(c.Session, teamId, model.PERMISSION_VIEW_TEAM) {
 			c.SetPermissionError(model.PERMISSION_VIEW_TEAM)
 			return
 		}
 
-		result, err := c.App.AutocompleteUsersInTeam(teamId, name, searchOptions, c.IsSystemAdmin())
-		if err != nil {
+		if c.Session.IsGuest() {
+			if autocomplete.Users, err = c.App.SearchUsersInUserChannels(c.Session.UserId, teamId, name, searchOptions, false); err != nil {
+				c.Err = err
+				return
+			}
+		} else {
+			result, err := c.App.AutocompleteUsersInTeam(teamId, name, searchOptions, c.IsSystemAdmin())
+			if err != nil {
+				c.Err = err
+				return
+			}
+
+			autocomplete.Users = result.InTeam
+		}
+
+		if len(name) > 0 {
+			if autocomplete.Groups, err = c.App.AutocompleteCustomGroups(name); err != nil {
+				c.Err = err
+				return
+			}
+		}
+	} else if c.Session.IsGuest() {
+		if autocomplete.Users, err = c.App.SearchUsersInUserChannels(c.Session.UserId, "", name, searchOptions, false); err != nil {
 			c.Err = err
 			return
 		}
-
-		autocomplete.Users = result.InTeam
 	} else {
 		// No permission check required
 		result, err := c.App.SearchUsersInTeam("", name, searchOptions, c.IsSystemAdmin())
@@ -539,6 +855,19 @@ func autocompleteUsers(c *Context, w http.ResponseWriter, r *http.Request) {
 		autocomplete.Users = result
 	}
 
+	filters := userAttributeFiltersFromQuery(r.URL.Query())
+	if autocomplete.Users, err = c.App.FilterUsersByAttributes(autocomplete.Users, filters, c.Session.UserId, c.IsSystemAdmin()); err != nil {
+		c.Err = err
+		return
+	}
+
+	if autocomplete.OutOfChannel != nil {
+		if autocomplete.OutOfChannel, err = c.App.FilterUsersByAttributes(autocomplete.OutOfChannel, filters, c.Session.UserId, c.IsSystemAdmin()); err != nil {
+			c.Err = err
+			return
+		}
+	}
+
 	if err != nil {
 		c.Err = err
 		return
@@ -559,7 +888,7 @@ func updateUser(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !c.App.SessionHasPermissionToUser(c.Session, user.Id) {
+	if !c.App.SessionHasPermissionToUser(c.Session, user.Id) && !c.App.SessionHasPermissionToManageUser(c.Session, user.Id) {
 		c.SetPermissionError(model.PERMISSION_EDIT_OTHER_USERS)
 		return
 	}
@@ -599,7 +928,7 @@ func patchUser(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !c.App.SessionHasPermissionToUser(c.Session, c.Params.UserId) {
+	if !c.App.SessionHasPermissionToUser(c.Session, c.Params.UserId) && !c.App.SessionHasPermissionToManageUser(c.Session, c.Params.UserId) {
 		c.SetPermissionError(model.PERMISSION_EDIT_OTHER_USERS)
 		return
 	}
@@ -641,7 +970,7 @@ func deleteUser(c *Context, w http.ResponseWriter, r *http.Request) {
 
 	userId := c.Params.UserId
 
-	if !c.App.SessionHasPermissionToUser(c.Session, userId) {
+	if !c.App.SessionHasPermissionToUser(c.Session, userId) && !c.App.SessionHasPermissionToManageUser(c.Session, userId) {
 		c.SetPermissionError(model.PERMISSION_EDIT_OTHER_USERS)
 		return
 	}
@@ -691,6 +1020,63 @@ func updateUserRoles(c *Context, w http.ResponseWriter, r *http.Request) {
 	ReturnStatusOK(w)
 }
 
+func promoteGuestToUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_PROMOTE_GUEST) {
+		c.SetPermissionError(model.PERMISSION_PROMOTE_GUEST)
+		return
+	}
+
+	user, err := c.App.GetUser(c.Params.UserId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	if _, err = c.App.PromoteGuestToUser(user); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAuditWithUserId(user.Id, "promoted guest to user")
+	ReturnStatusOK(w)
+}
+
+func demoteUserToGuest(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId()
+	if c.Err != nil {
+		return
+	}
+
+	if !*c.App.Config().GuestAccountsSettings.Enable {
+		c.Err = model.NewAppError("demoteUserToGuest", "api.user.demote_user_to_guest.disabled.app_error", nil, "", http.StatusNotImplemented)
+		return
+	}
+
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_DEMOTE_TO_GUEST) {
+		c.SetPermissionError(model.PERMISSION_DEMOTE_TO_GUEST)
+		return
+	}
+
+	user, err := c.App.GetUser(c.Params.UserId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	if _, err = c.App.DemoteUserToGuest(user); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAuditWithUserId(user.Id, "demoted user to guest")
+	ReturnStatusOK(w)
+}
+
 func updateUserActive(c *Context, w http.ResponseWriter, r *http.Request) {
 	c.RequireUserId()
 	if c.Err != nil {
@@ -708,7 +1094,7 @@ func updateUserActive(c *Context, w http.ResponseWriter, r *http.Request) {
 	// true when you're trying to de-activate yourself
 	isSelfDeactive := !active && c.Params.UserId == c.Session.UserId
 
-	if !isSelfDeactive && !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+	if !isSelfDeactive && !c.App.SessionHasPermissionToManageUser(c.Session, c.Params.UserId) {
 		c.Err = model.NewAppError("updateUserActive", "api.user.update_active.permissions.app_error", nil, "userId="+c.Params.UserId, http.StatusForbidden)
 		return
 	}
@@ -729,6 +1115,58 @@ func updateUserActive(c *Context, w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func scheduleUserDeactivation(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId()
+	if c.Err != nil {
+		return
+	}
+
+	props := model.StringInterfaceFromJson(r.Body)
+
+	deactivateAt, ok := props["deactivate_at"].(float64)
+	if !ok || deactivateAt <= 0 {
+		c.SetInvalidParam("deactivate_at")
+		return
+	}
+
+	if !c.App.SessionHasPermissionToManageUser(c.Session, c.Params.UserId) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_USERS)
+		return
+	}
+
+	user, err := c.App.ScheduleUserDeactivation(c.Params.UserId, int64(deactivateAt))
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAuditWithUserId(user.Id, fmt.Sprintf("deactivate_at=%v", user.DeactivateAt))
+	c.App.SanitizeProfile(user, true)
+	w.Write([]byte(user.ToJson()))
+}
+
+func cancelUserDeactivation(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionToManageUser(c.Session, c.Params.UserId) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_USERS)
+		return
+	}
+
+	user, err := c.App.CancelUserDeactivation(c.Params.UserId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAuditWithUserId(user.Id, "")
+	c.App.SanitizeProfile(user, true)
+	w.Write([]byte(user.ToJson()))
+}
+
 func updateUserAuth(c *Context, w http.ResponseWriter, r *http.Request) {
 	if !c.IsSystemAdmin() {
 		c.SetPermissionError(model.PERMISSION_EDIT_OTHER_USERS)
@@ -873,7 +1311,7 @@ func updatePassword(c *Context, w http.ResponseWriter, r *http.Request) {
 		}
 
 		err = c.App.UpdatePasswordAsUser(c.Params.UserId, currentPassword, newPassword)
-	} else if c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+	} else if c.App.SessionHasPermissionToManageUser(c.Session, c.Params.UserId) {
 		err = c.App.UpdatePasswordByUserIdSendEmail(c.Params.UserId, newPassword, c.T("api.user.reset_password.method"))
 	} else {
 		err = model.NewAppError("updatePassword", "api.user.update_password.context.app_error", nil, "", http.StatusForbidden)
@@ -941,11 +1379,21 @@ func login(c *Context, w http.ResponseWriter, r *http.Request) {
 	mfaToken := props["token"]
 	deviceId := props["device_id"]
 	ldapOnly := props["ldap_only"] == "true"
+	captchaToken := props["captcha_token"]
+	ipAddress := c.App.GetIpAddress(r)
 
 	c.LogAuditWithUserId(id, "attempt - login_id="+loginId)
+
+	if err := c.App.CheckLoginCaptcha(id, loginId, ipAddress, captchaToken); err != nil {
+		c.LogAuditWithUserId(id, "failure - captcha - login_id="+loginId)
+		c.Err = err
+		return
+	}
+
 	user, err := c.App.AuthenticateUserForLogin(id, loginId, password, mfaToken, ldapOnly)
 	if err != nil {
 		c.LogAuditWithUserId(id, "failure - login_id="+loginId)
+		c.App.RecordLoginFailure(id, loginId, ipAddress)
 		c.Err = err
 		return
 	}
@@ -1052,6 +1500,30 @@ func revokeSession(c *Context, w http.ResponseWriter, r *http.Request) {
 	ReturnStatusOK(w)
 }
 
+func impersonateUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_IMPERSONATE_USER) {
+		c.SetPermissionError(model.PERMISSION_IMPERSONATE_USER)
+		return
+	}
+
+	session, err := c.App.CreateImpersonationSession(&c.Session, c.Params.UserId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("impersonated_user_id=" + session.UserId + " impersonation_session_id=" + session.Id)
+
+	w.Header().Set(model.HEADER_TOKEN, session.Token)
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(session.ToJson()))
+}
+
 func revokeAllSessionsForUser(c *Context, w http.ResponseWriter, r *http.Request) {
 	c.RequireUserId()
 	if c.Err != nil {
@@ -1139,6 +1611,170 @@ func getUserAudits(c *Context, w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func getNotificationTraceStatus(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	w.Write([]byte(c.App.GetNotificationTraceStatus(c.Params.UserId).ToJson()))
+}
+
+func setNotificationTrace(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId()
+	if c.Err != nil {
+		return
+	}
+
+	requested := model.NotificationTraceStatusFromJson(r.Body)
+	if requested == nil {
+		c.SetInvalidParam("notification_trace")
+		return
+	}
+
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	if _, err := c.App.GetUser(c.Params.UserId); err != nil {
+		c.Err = err
+		return
+	}
+
+	status, err := c.App.SetNotificationTrace(c.Params.UserId, requested.TTLSeconds)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit(fmt.Sprintf("user_id=%v ttl_seconds=%v", c.Params.UserId, requested.TTLSeconds))
+	w.Write([]byte(status.ToJson()))
+}
+
+func getNotificationTrace(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId().RequirePostId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	trace, err := c.App.GetNotificationTrace(c.Params.UserId, c.Params.PostId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(trace.ToJson()))
+}
+
+func publishUserTyping(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId()
+	if c.Err != nil {
+		return
+	}
+
+	typingRequest := model.TypingRequestFromJson(r.Body)
+	if typingRequest == nil {
+		c.SetInvalidParam("typing_request")
+		return
+	}
+
+	if !model.IsValidId(typingRequest.ChannelId) {
+		c.SetInvalidParam("channel_id")
+		return
+	}
+
+	if typingRequest.ParentId != "" && !model.IsValidId(typingRequest.ParentId) {
+		c.SetInvalidParam("parent_id")
+		return
+	}
+
+	if !c.App.SessionHasPermissionToUser(c.Session, c.Params.UserId) {
+		c.SetPermissionError(model.PERMISSION_EDIT_OTHER_USERS)
+		return
+	}
+
+	if !c.App.SessionHasPermissionToChannel(c.Session, typingRequest.ChannelId, model.PERMISSION_READ_CHANNEL) {
+		c.SetPermissionError(model.PERMISSION_READ_CHANNEL)
+		return
+	}
+
+	if err := c.App.PublishUserTyping(c.Params.UserId, typingRequest.ChannelId, typingRequest.ParentId); err != nil {
+		c.Err = err
+		return
+	}
+
+	ReturnStatusOK(w)
+}
+
+func getBlockedUsers(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionToUser(c.Session, c.Params.UserId) {
+		c.SetPermissionError(model.PERMISSION_EDIT_OTHER_USERS)
+		return
+	}
+
+	users, err := c.App.GetBlockedUsers(c.Params.UserId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(model.UserListToJson(users)))
+}
+
+func blockUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId().RequireBlockedUserId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionToUser(c.Session, c.Params.UserId) {
+		c.SetPermissionError(model.PERMISSION_EDIT_OTHER_USERS)
+		return
+	}
+
+	if err := c.App.BlockUser(c.Params.UserId, c.Params.BlockedUserId); err != nil {
+		c.Err = err
+		return
+	}
+
+	ReturnStatusOK(w)
+}
+
+func unblockUser(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId().RequireBlockedUserId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionToUser(c.Session, c.Params.UserId) {
+		c.SetPermissionError(model.PERMISSION_EDIT_OTHER_USERS)
+		return
+	}
+
+	if err := c.App.UnblockUser(c.Params.UserId, c.Params.BlockedUserId); err != nil {
+		c.Err = err
+		return
+	}
+
+	ReturnStatusOK(w)
+}
+
 func verifyUserEmail(c *Context, w http.ResponseWriter, r *http.Request) {
 	props := model.MapFromJson(r.Body)
 
@@ -1158,6 +1794,78 @@ func verifyUserEmail(c *Context, w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func undoEmailChange(c *Context, w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		undoEmailChangeFromPage(c, w, r)
+		return
+	}
+
+	props := model.MapFromJson(r.Body)
+
+	token := props["token"]
+	if len(token) != model.TOKEN_SIZE {
+		c.SetInvalidParam("token")
+		return
+	}
+
+	if err := c.App.UndoEmailChange(token); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("Email Change Undone")
+	ReturnStatusOK(w)
+}
+
+// undoEmailChangeFromLink shows a page from the link sent to the user's old address that asks them to confirm that
+// they want to undo the email change. Nothing is changed until the page is submitted since mail scanners and link
+// previews open links without anyone clicking on them.
+func undoEmailChangeFromLink(c *Context, w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if len(token) != model.TOKEN_SIZE {
+		err := model.NewAppError("undoEmailChangeFromLink", "api.user.email_change.undo.bad_link.app_error", nil, "", http.StatusBadRequest)
+		err.Translate(c.T)
+		utils.RenderWebAppError(w, r, err, c.App.AsymmetricSigningKey())
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+
+	page := utils.NewHTMLTemplate(c.App.HTMLTemplates(), "email_change_undo")
+	page.Props["Title"] = c.T("api.user.email_change.undo.title")
+	page.Props["Info"] = c.T("api.user.email_change.undo.info")
+	page.Props["Button"] = c.T("api.user.email_change.undo.button")
+	page.Props["Action"] = c.GetSiteURLHeader() + model.API_URL_SUFFIX + "/users/email/undo_change"
+	page.Props["Token"] = token
+	page.RenderToWriter(w)
+}
+
+// undoEmailChangeFromPage undoes an email change when the page shown by undoEmailChangeFromLink is submitted and sends
+// the user to sign in again. Errors are shown on the error page since the page is submitted by a browser.
+func undoEmailChangeFromPage(c *Context, w http.ResponseWriter, r *http.Request) {
+	token := r.PostFormValue("token")
+
+	var err *model.AppError
+	if len(token) != model.TOKEN_SIZE {
+		err = model.NewAppError("undoEmailChangeFromPage", "api.user.email_change.undo.bad_link.app_error", nil, "", http.StatusBadRequest)
+	} else {
+		err = c.App.UndoEmailChange(token)
+	}
+
+	if err != nil {
+		err.Translate(c.T)
+		utils.RenderWebAppError(w, r, err, c.App.AsymmetricSigningKey())
+		return
+	}
+
+	c.LogAudit("Email Change Undone")
+	http.Redirect(w, r, c.GetSiteURLHeader()+"/login?extra=email_change_undone", http.StatusFound)
+}
+
 func sendVerificationEmail(c *Context, w http.ResponseWriter, r *http.Request) {
 	props := model.MapFromJson(r.Body)
 
@@ -1177,7 +1885,7 @@ func sendVerificationEmail(c *Context, w http.ResponseWriter, r *http.Request) {
 	err = c.App.SendEmailVerification(user)
 	if err != nil {
 		// Don't want to leak whether the email is valid or not
-		mlog.Error(err.Error())
+		c.Log.Error(err.Error())
 		ReturnStatusOK(w)
 		return
 	}
@@ -0,0 +1,197 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// USER_ATTRIBUTE_FILTER_PARAM_PREFIX is the prefix of query parameters that filter autocompleted users by their
+// attributes, such as ?attribute_<field_id>=Engineering.
+const USER_ATTRIBUTE_FILTER_PARAM_PREFIX = "attribute_"
+
+func (api *API) InitUserAttribute() {
+	api.BaseRoutes.UserAttributeFields.Handle("", api.ApiSessionRequired(createUserAttributeField)).Methods("POST")
+	api.BaseRoutes.UserAttributeFields.Handle("", api.ApiSessionRequired(getUserAttributeFields)).Methods("GET")
+	api.BaseRoutes.UserAttributeField.Handle("", api.ApiSessionRequired(getUserAttributeField)).Methods("GET")
+	api.BaseRoutes.UserAttributeField.Handle("/patch", api.ApiSessionRequired(patchUserAttributeField)).Methods("PUT")
+	api.BaseRoutes.UserAttributeField.Handle("", api.ApiSessionRequired(deleteUserAttributeField)).Methods("DELETE")
+
+	api.BaseRoutes.User.Handle("/attributes", api.ApiSessionRequired(getUserAttributes)).Methods("GET")
+	api.BaseRoutes.User.Handle("/attributes", api.ApiSessionRequired(updateUserAttributes)).Methods("PUT")
+}
+
+// userAttributeFiltersFromQuery returns the attribute values that users should be filtered by, keyed by field id.
+func userAttributeFiltersFromQuery(query url.Values) model.StringMap {
+	filters := model.StringMap{}
+	for key, values := range query {
+		if strings.HasPrefix(key, USER_ATTRIBUTE_FILTER_PARAM_PREFIX) && len(values) > 0 {
+			filters[strings.TrimPrefix(key, USER_ATTRIBUTE_FILTER_PARAM_PREFIX)] = values[0]
+		}
+	}
+
+	return filters
+}
+
+func createUserAttributeField(c *Context, w http.ResponseWriter, r *http.Request) {
+	field := model.UserAttributeFieldFromJson(r.Body)
+	if field == nil {
+		c.SetInvalidParam("field")
+		return
+	}
+
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	created, err := c.App.CreateUserAttributeField(field)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("name=" + created.Name)
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(created.ToJson()))
+}
+
+func getUserAttributeFields(c *Context, w http.ResponseWriter, r *http.Request) {
+	fields, err := c.App.GetUserAttributeFields()
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(model.UserAttributeFieldListToJson(fields)))
+}
+
+func getUserAttributeField(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireFieldId()
+	if c.Err != nil {
+		return
+	}
+
+	field, err := c.App.GetUserAttributeField(c.Params.FieldId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(field.ToJson()))
+}
+
+func patchUserAttributeField(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireFieldId()
+	if c.Err != nil {
+		return
+	}
+
+	patch := model.UserAttributeFieldPatchFromJson(r.Body)
+	if patch == nil {
+		c.SetInvalidParam("field")
+		return
+	}
+
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	field, err := c.App.GetUserAttributeField(c.Params.FieldId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	patched, err := c.App.PatchUserAttributeField(field, patch)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("name=" + patched.Name)
+	w.Write([]byte(patched.ToJson()))
+}
+
+func deleteUserAttributeField(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireFieldId()
+	if c.Err != nil {
+		return
+	}
+
+	if !c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	field, err := c.App.GetUserAttributeField(c.Params.FieldId)
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	if err := c.App.DeleteUserAttributeField(field.Id); err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("name=" + field.Name)
+	ReturnStatusOK(w)
+}
+
+func getUserAttributes(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId()
+	if c.Err != nil {
+		return
+	}
+
+	if canSee, err := c.App.SessionCanSeeUser(c.Session, c.Params.UserId); err != nil {
+		c.Err = err
+		return
+	} else if !canSee {
+		c.Err = model.NewAppError("getUserAttributes", "api.user.guest_restricted.app_error", nil, "", http.StatusForbidden)
+		return
+	}
+
+	attributes, err := c.App.GetUserAttributes(c.Params.UserId, c.Session.UserId == c.Params.UserId || c.IsSystemAdmin())
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	w.Write([]byte(model.MapToJson(attributes)))
+}
+
+func updateUserAttributes(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.RequireUserId()
+	if c.Err != nil {
+		return
+	}
+
+	values := model.MapFromJson(r.Body)
+
+	if !c.App.SessionHasPermissionToUser(c.Session, c.Params.UserId) {
+		c.SetPermissionError(model.PERMISSION_EDIT_OTHER_USERS)
+		return
+	}
+
+	if _, err := c.App.GetUser(c.Params.UserId); err != nil {
+		c.Err = err
+		return
+	}
+
+	attributes, err := c.App.UpdateUserAttributes(c.Params.UserId, values, c.App.SessionHasPermissionTo(c.Session, model.PERMISSION_MANAGE_SYSTEM))
+	if err != nil {
+		c.Err = err
+		return
+	}
+
+	c.LogAudit("")
+	w.Write([]byte(model.MapToJson(attributes)))
+}
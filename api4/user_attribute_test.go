@@ -0,0 +1,174 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestUserAttributeFields(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	field := &model.UserAttributeField{Name: "Office " + model.NewId(), Type: model.USER_ATTRIBUTE_TYPE_SELECT, Options: model.StringArray{"Berlin"}}
+
+	_, resp := Client.CreateUserAttributeField(field)
+	CheckForbiddenStatus(t, resp)
+
+	created, resp := th.SystemAdminClient.CreateUserAttributeField(field)
+	CheckNoError(t, resp)
+	CheckCreatedStatus(t, resp)
+	assert.Equal(t, model.USER_ATTRIBUTE_VISIBILITY_PUBLIC, created.Visibility)
+
+	_, resp = th.SystemAdminClient.CreateUserAttributeField(&model.UserAttributeField{Name: "Office", Type: model.USER_ATTRIBUTE_TYPE_SELECT})
+	CheckBadRequestStatus(t, resp)
+
+	fields, resp := Client.GetUserAttributeFields()
+	CheckNoError(t, resp)
+	found := false
+	for _, f := range fields {
+		found = found || f.Id == created.Id
+	}
+	assert.True(t, found)
+
+	fetched, resp := Client.GetUserAttributeField(created.Id)
+	CheckNoError(t, resp)
+	assert.Equal(t, created.Name, fetched.Name)
+
+	_, resp = Client.GetUserAttributeField(model.NewId())
+	CheckNotFoundStatus(t, resp)
+
+	options := model.StringArray{"Berlin", "Toronto"}
+	_, resp = Client.PatchUserAttributeField(created.Id, &model.UserAttributeFieldPatch{Options: &options})
+	CheckForbiddenStatus(t, resp)
+
+	patched, resp := th.SystemAdminClient.PatchUserAttributeField(created.Id, &model.UserAttributeFieldPatch{Options: &options})
+	CheckNoError(t, resp)
+	assert.Equal(t, options, patched.Options)
+
+	_, resp = Client.DeleteUserAttributeField(created.Id)
+	CheckForbiddenStatus(t, resp)
+
+	ok, resp := th.SystemAdminClient.DeleteUserAttributeField(created.Id)
+	CheckNoError(t, resp)
+	assert.True(t, ok)
+
+	_, resp = Client.GetUserAttributeField(created.Id)
+	CheckNotFoundStatus(t, resp)
+
+	Client.Logout()
+	_, resp = Client.GetUserAttributeFields()
+	CheckUnauthorizedStatus(t, resp)
+}
+
+func TestUpdateUserAttributes(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	department, resp := th.SystemAdminClient.CreateUserAttributeField(&model.UserAttributeField{Name: "Department " + model.NewId(), Type: model.USER_ATTRIBUTE_TYPE_TEXT})
+	CheckNoError(t, resp)
+	employeeId, resp := th.SystemAdminClient.CreateUserAttributeField(&model.UserAttributeField{Name: "Employee ID " + model.NewId(), Type: model.USER_ATTRIBUTE_TYPE_TEXT, ReadOnly: true})
+	CheckNoError(t, resp)
+	startDate, resp := th.SystemAdminClient.CreateUserAttributeField(&model.UserAttributeField{Name: "Start Date " + model.NewId(), Type: model.USER_ATTRIBUTE_TYPE_DATE, Visibility: model.USER_ATTRIBUTE_VISIBILITY_PRIVATE})
+	CheckNoError(t, resp)
+
+	values, resp := Client.UpdateUserAttributes(th.BasicUser.Id, map[string]string{department.Id: "Engineering", startDate.Id: "2018-01-02"})
+	CheckNoError(t, resp)
+	assert.Equal(t, map[string]string{department.Id: "Engineering", startDate.Id: "2018-01-02"}, values)
+
+	_, resp = Client.UpdateUserAttributes(th.BasicUser.Id, map[string]string{startDate.Id: "tomorrow"})
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = Client.UpdateUserAttributes(th.BasicUser.Id, map[string]string{employeeId.Id: "E1"})
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = Client.UpdateUserAttributes(th.BasicUser2.Id, map[string]string{department.Id: "Sales"})
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = th.SystemAdminClient.UpdateUserAttributes(th.BasicUser.Id, map[string]string{employeeId.Id: "E1"})
+	CheckNoError(t, resp)
+
+	values, resp = Client.GetUserAttributes(th.BasicUser.Id)
+	CheckNoError(t, resp)
+	assert.Equal(t, map[string]string{department.Id: "Engineering", employeeId.Id: "E1", startDate.Id: "2018-01-02"}, values)
+
+	// Private attributes are left out of other users' profiles
+	th.LoginBasic2()
+
+	values, resp = Client.GetUserAttributes(th.BasicUser.Id)
+	CheckNoError(t, resp)
+	assert.Equal(t, map[string]string{department.Id: "Engineering", employeeId.Id: "E1"}, values)
+
+	user, resp := Client.GetUser(th.BasicUser.Id, "")
+	CheckNoError(t, resp)
+	assert.Equal(t, model.StringMap{department.Id: "Engineering", employeeId.Id: "E1"}, user.Attributes)
+
+	user, resp = th.SystemAdminClient.GetUserByUsername(th.BasicUser.Username, "")
+	CheckNoError(t, resp)
+	assert.Equal(t, "2018-01-02", user.Attributes[startDate.Id])
+
+	// Removing the field removes it from profiles
+	_, resp = th.SystemAdminClient.DeleteUserAttributeField(department.Id)
+	CheckNoError(t, resp)
+
+	user, resp = Client.GetUser(th.BasicUser.Id, "")
+	CheckNoError(t, resp)
+	assert.Equal(t, model.StringMap{employeeId.Id: "E1"}, user.Attributes)
+}
+
+func TestSearchUsersByAttributes(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	office, resp := th.SystemAdminClient.CreateUserAttributeField(&model.UserAttributeField{Name: "Office " + model.NewId(), Type: model.USER_ATTRIBUTE_TYPE_SELECT, Options: model.StringArray{"Berlin", "Toronto"}})
+	CheckNoError(t, resp)
+	salary, resp := th.SystemAdminClient.CreateUserAttributeField(&model.UserAttributeField{Name: "Salary " + model.NewId(), Type: model.USER_ATTRIBUTE_TYPE_TEXT, Visibility: model.USER_ATTRIBUTE_VISIBILITY_PRIVATE})
+	CheckNoError(t, resp)
+
+	_, resp = th.SystemAdminClient.UpdateUserAttributes(th.BasicUser.Id, map[string]string{office.Id: "Berlin", salary.Id: "100"})
+	CheckNoError(t, resp)
+	_, resp = th.SystemAdminClient.UpdateUserAttributes(th.BasicUser2.Id, map[string]string{office.Id: "Toronto"})
+	CheckNoError(t, resp)
+
+	search := &model.UserSearch{Term: "user", TeamId: th.BasicTeam.Id, Attributes: model.StringMap{office.Id: "berlin"}}
+	users, resp := Client.SearchUsers(search)
+	CheckNoError(t, resp)
+	assert.True(t, findUserInList(th.BasicUser.Id, users))
+	assert.False(t, findUserInList(th.BasicUser2.Id, users))
+	for _, user := range users {
+		assert.Equal(t, "Berlin", user.Attributes[office.Id])
+	}
+
+	search.Attributes = model.StringMap{salary.Id: "100"}
+	_, resp = Client.SearchUsers(search)
+	CheckBadRequestStatus(t, resp)
+
+	users, resp = th.SystemAdminClient.SearchUsers(search)
+	CheckNoError(t, resp)
+	assert.True(t, findUserInList(th.BasicUser.Id, users))
+
+	autocomplete, resp := Client.AutocompleteUsersInTeamWithAttributes(th.BasicTeam.Id, "", map[string]string{office.Id: "Toronto"})
+	CheckNoError(t, resp)
+	require.NotEmpty(t, autocomplete.Users)
+	assert.True(t, findUserInList(th.BasicUser2.Id, autocomplete.Users))
+	assert.False(t, findUserInList(th.BasicUser.Id, autocomplete.Users))
+
+	// Removing an option stops users that had it from matching
+	options := model.StringArray{"Toronto"}
+	_, resp = th.SystemAdminClient.PatchUserAttributeField(office.Id, &model.UserAttributeFieldPatch{Options: &options})
+	CheckNoError(t, resp)
+
+	search.Attributes = model.StringMap{office.Id: "Berlin"}
+	users, resp = Client.SearchUsers(search)
+	CheckNoError(t, resp)
+	assert.False(t, findUserInList(th.BasicUser.Id, users))
+}
@@ -4,8 +4,13 @@
 package api4
 
 import (
+	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -322,6 +327,94 @@ func TestGetMe(t *testing.T) {
 	CheckUnauthorizedStatus(t, resp)
 }
 
+func TestGetBootData(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	otherTeam := th.CreateTeamWithClient(th.SystemAdminClient)
+	th.LinkUserToTeam(th.BasicUser, otherTeam)
+	th.App.AddUserToChannel(th.BasicUser, th.CreateChannelWithClientAndTeam(th.SystemAdminClient, model.CHANNEL_OPEN, otherTeam.Id))
+
+	boot, resp := Client.GetBootData()
+	CheckNoError(t, resp)
+	require.NotNil(t, boot)
+	assert.Empty(t, boot.Errors)
+
+	t.Run("matches the individual endpoints", func(t *testing.T) {
+		user, resp := Client.GetMe("")
+		CheckNoError(t, resp)
+		assert.Equal(t, user, boot.User)
+		CheckUserSanitization(t, boot.User)
+
+		teams, resp := Client.GetTeamsForUser(th.BasicUser.Id, "")
+		CheckNoError(t, resp)
+		assert.Equal(t, teams, boot.Teams)
+		require.Len(t, boot.Teams, 2)
+
+		teamMembers, resp := Client.GetTeamMembersForUser(th.BasicUser.Id, "")
+		CheckNoError(t, resp)
+		assert.Equal(t, teamMembers, boot.TeamMembers)
+
+		require.Len(t, boot.Channels, 2)
+		require.Len(t, boot.ChannelMembers, 2)
+		for _, team := range teams {
+			channels, resp := Client.GetChannelsForTeamForUser(team.Id, th.BasicUser.Id, "")
+			CheckNoError(t, resp)
+			require.NotNil(t, boot.Channels[team.Id])
+			assert.Equal(t, channels, []*model.Channel(*boot.Channels[team.Id]))
+
+			channelMembers, resp := Client.GetChannelMembersForUser(th.BasicUser.Id, team.Id, "")
+			CheckNoError(t, resp)
+			assert.Equal(t, channelMembers, boot.ChannelMembers[team.Id])
+		}
+
+		preferences, resp := Client.GetPreferences(th.BasicUser.Id)
+		CheckNoError(t, resp)
+		assert.Equal(t, preferences, boot.Preferences)
+
+		license, resp := Client.GetOldClientLicense("")
+		CheckNoError(t, resp)
+		assert.Equal(t, license, boot.License)
+
+		config, resp := Client.GetOldClientConfig("")
+		CheckNoError(t, resp)
+		assert.Equal(t, config, boot.Config)
+	})
+
+	t.Run("server timing only in developer mode", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableDeveloper = false })
+
+		r, err := Client.DoApiGet(Client.GetUserRoute(model.ME)+"/boot", "")
+		require.Nil(t, err)
+		defer r.Body.Close()
+		assert.Empty(t, r.Header.Get(model.HEADER_SERVER_TIMING))
+
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableDeveloper = true })
+
+		r, err = Client.DoApiGet(Client.GetUserRoute(model.ME)+"/boot", "")
+		require.Nil(t, err)
+		defer r.Body.Close()
+
+		timing := r.Header.Get(model.HEADER_SERVER_TIMING)
+		for _, section := range []string{model.BOOT_SECTION_USER, model.BOOT_SECTION_CHANNELS, model.BOOT_SECTION_CONFIG, "total"} {
+			assert.Contains(t, timing, section+";dur=")
+		}
+	})
+
+	t.Run("only for the current user", func(t *testing.T) {
+		_, err := th.SystemAdminClient.DoApiGet(th.SystemAdminClient.GetUserRoute(th.BasicUser.Id)+"/boot", "")
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusForbidden, err.StatusCode)
+	})
+
+	t.Run("requires a session", func(t *testing.T) {
+		Client.Logout()
+		_, resp := Client.GetBootData()
+		CheckUnauthorizedStatus(t, resp)
+	})
+}
+
 func TestGetUser(t *testing.T) {
 	th := Setup().InitBasic().InitSystemAdmin()
 	defer th.TearDown()
@@ -805,6 +898,50 @@ func TestAutocompleteUsers(t *testing.T) {
 	}
 }
 
+func TestAutocompleteUsersExclusions(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+	Client := th.Client
+
+	prefix := "excl" + model.NewId()[:10]
+
+	active := store.Must(th.App.Srv.Store.User().Save(&model.User{Email: th.GenerateTestEmail(), Username: prefix + "active"})).(*model.User)
+	bot := store.Must(th.App.Srv.Store.User().Save(&model.User{Email: th.GenerateTestEmail(), Username: prefix + "bot", IsBot: true})).(*model.User)
+	deactivated := store.Must(th.App.Srv.Store.User().Save(&model.User{Email: th.GenerateTestEmail(), Username: prefix + "deactivated"})).(*model.User)
+
+	for _, user := range []*model.User{active, bot, deactivated} {
+		th.LinkUserToTeam(user, th.BasicTeam)
+		th.AddUserToChannel(user, th.BasicChannel)
+	}
+
+	_, err := th.App.UpdateActive(deactivated, false)
+	require.Nil(t, err)
+
+	userIds := func(users []*model.User) []string {
+		ids := []string{}
+		for _, user := range users {
+			ids = append(ids, user.Id)
+		}
+		return ids
+	}
+
+	rusers, resp := Client.AutocompleteUsersInChannel(th.BasicTeam.Id, th.BasicChannel.Id, prefix, "")
+	CheckNoError(t, resp)
+	assert.Equal(t, []string{active.Id, bot.Id}, userIds(rusers.Users))
+
+	rusers, resp = Client.AutocompleteUsersInChannelWithOptions(th.BasicTeam.Id, th.BasicChannel.Id, prefix, true, false)
+	CheckNoError(t, resp)
+	assert.Equal(t, []string{active.Id, bot.Id, deactivated.Id}, userIds(rusers.Users))
+
+	rusers, resp = Client.AutocompleteUsersInChannelWithOptions(th.BasicTeam.Id, th.BasicChannel.Id, prefix, false, true)
+	CheckNoError(t, resp)
+	assert.Equal(t, []string{active.Id}, userIds(rusers.Users))
+
+	rusers, resp = Client.AutocompleteUsersInChannelWithOptions(th.BasicTeam.Id, th.BasicChannel.Id, prefix, true, true)
+	CheckNoError(t, resp)
+	assert.Equal(t, []string{active.Id, deactivated.Id}, userIds(rusers.Users))
+}
+
 func TestGetProfileImage(t *testing.T) {
 	th := Setup().InitBasic().InitSystemAdmin()
 	defer th.TearDown()
@@ -817,10 +954,20 @@ func TestGetProfileImage(t *testing.T) {
 		t.Fatal("Should not be empty")
 	}
 
-	_, resp = Client.GetProfileImage(user.Id, resp.Etag)
-	if resp.StatusCode == http.StatusNotModified {
-		t.Fatal("Shouldn't have hit etag")
-	}
+	etag := resp.Etag
+	require.NotEmpty(t, etag)
+	data, resp = Client.GetProfileImage(user.Id, etag)
+	CheckEtag(t, data, resp)
+
+	// The default image has the user's initials, so it changes when their name does
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.TeamSettings.TeammateNameDisplay = model.SHOW_FULLNAME })
+	user.FirstName = "Renamed"
+	_, resp = Client.UpdateUser(user)
+	CheckNoError(t, resp)
+	renamed, resp := Client.GetProfileImage(user.Id, etag)
+	CheckNoError(t, resp)
+	assert.NotEqual(t, etag, resp.Etag)
+	assert.NotEmpty(t, renamed)
 
 	_, resp = Client.GetProfileImage("junk", "")
 	CheckBadRequestStatus(t, resp)
@@ -835,10 +982,7 @@ func TestGetProfileImage(t *testing.T) {
 	_, resp = th.SystemAdminClient.GetProfileImage(user.Id, "")
 	CheckNoError(t, resp)
 
-	info := &model.FileInfo{Path: "/users/" + user.Id + "/profile.png"}
-	if err := th.cleanupTestFile(info); err != nil {
-		t.Fatal(err)
-	}
+	require.Nil(t, th.App.RemoveDirectory("users/"+user.Id+"/initials"))
 }
 
 func TestGetUsersByIds(t *testing.T) {
@@ -1190,6 +1334,57 @@ func TestUpdateUserRoles(t *testing.T) {
 	CheckBadRequestStatus(t, resp)
 }
 
+func TestScheduleUserDeactivation(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+
+	Client := th.Client
+	user := th.BasicUser2
+	deactivateAt := model.GetMillis() + 60*60*1000
+
+	_, resp := Client.ScheduleUserDeactivation(user.Id, deactivateAt)
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = th.SystemAdminClient.ScheduleUserDeactivation(user.Id, model.GetMillis()-1000)
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = th.SystemAdminClient.ScheduleUserDeactivation(model.NewId(), deactivateAt)
+	CheckNotFoundStatus(t, resp)
+
+	ruser, resp := th.SystemAdminClient.ScheduleUserDeactivation(user.Id, deactivateAt)
+	CheckNoError(t, resp)
+	assert.Equal(t, deactivateAt, ruser.DeactivateAt)
+
+	users, resp := th.SystemAdminClient.GetUsers(0, 1000, "")
+	CheckNoError(t, resp)
+	found := false
+	for _, u := range users {
+		if u.Id == user.Id {
+			found = true
+			assert.Equal(t, deactivateAt, u.DeactivateAt, "admins should see the pending deactivation")
+		}
+	}
+	assert.True(t, found)
+
+	users, resp = Client.GetUsers(0, 1000, "")
+	CheckNoError(t, resp)
+	for _, u := range users {
+		assert.Equal(t, int64(0), u.DeactivateAt, "only admins should see pending deactivations")
+	}
+
+	_, resp = Client.CancelUserDeactivation(user.Id)
+	CheckForbiddenStatus(t, resp)
+
+	ruser, resp = th.SystemAdminClient.CancelUserDeactivation(user.Id)
+	CheckNoError(t, resp)
+	assert.Equal(t, int64(0), ruser.DeactivateAt)
+
+	ruser, resp = th.SystemAdminClient.GetUser(user.Id, "")
+	CheckNoError(t, resp)
+	assert.Equal(t, int64(0), ruser.DeactivateAt)
+	assert.Equal(t, int64(0), ruser.DeleteAt)
+}
+
 func TestUpdateUserActive(t *testing.T) {
 	th := Setup().InitBasic().InitSystemAdmin()
 	defer th.TearDown()
@@ -1284,6 +1479,157 @@ func TestGetUsers(t *testing.T) {
 	CheckUnauthorizedStatus(t, resp)
 }
 
+func TestGetUsersWithFields(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+	Client := th.Client
+
+	th.App.UpdateConfig(func(cfg *model.Config) { cfg.PrivacySettings.ShowEmailAddress = false })
+
+	full, err := Client.DoApiGet("/users?per_page=200", "")
+	require.Nil(t, err)
+	fullBody, _ := ioutil.ReadAll(full.Body)
+	full.Body.Close()
+
+	sparse, err := Client.DoApiGet("/users?per_page=200&fields=id,username,email", "")
+	require.Nil(t, err)
+	sparseBody, _ := ioutil.ReadAll(sparse.Body)
+	sparse.Body.Close()
+
+	assert.True(t, len(sparseBody) < len(fullBody)/2, "sparse payload should be much smaller")
+
+	rusers, resp := Client.GetUsersWithFields(0, 200, []string{"id", "username", "email"})
+	CheckNoError(t, resp)
+	require.NotEmpty(t, rusers)
+	for _, u := range rusers {
+		assert.NotEmpty(t, u.Id)
+		assert.NotEmpty(t, u.Username)
+		assert.Empty(t, u.Email, "email should still be hidden")
+		assert.Empty(t, u.Roles)
+		assert.Empty(t, u.NotifyProps)
+	}
+
+	_, resp = Client.GetUsersWithFields(0, 200, []string{"id", "password"})
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = Client.GetUsersWithFields(0, 200, []string{"notify_props"})
+	CheckBadRequestStatus(t, resp)
+}
+
+func TestGetUsersWithFilter(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	team := th.CreateTeamWithClient(th.SystemAdminClient)
+
+	ldapUser := th.CreateUser()
+	th.LinkUserToTeam(ldapUser, team)
+	store.Must(th.App.Srv.Store.User().UpdateAuthData(ldapUser.Id, model.USER_AUTH_SERVICE_LDAP, model.NewString(model.NewId()), "", false))
+
+	inactiveLdapUser := th.CreateUser()
+	th.LinkUserToTeam(inactiveLdapUser, team)
+	store.Must(th.App.Srv.Store.User().UpdateAuthData(inactiveLdapUser.Id, model.USER_AUTH_SERVICE_LDAP, model.NewString(model.NewId()), "", false))
+	th.UpdateActiveUser(inactiveLdapUser, false)
+
+	emailUser := th.CreateUser()
+	th.LinkUserToTeam(emailUser, team)
+
+	beforeLogin := model.GetMillis()
+	time.Sleep(time.Millisecond)
+	_, resp := th.CreateClient().Login(emailUser.Email, emailUser.Password)
+	CheckNoError(t, resp)
+
+	active := true
+	inactive := false
+
+	for name, tc := range map[string]struct {
+		Filter   model.UserFilter
+		Expected []string
+	}{
+		"auth service": {
+			Filter:   model.UserFilter{AuthService: model.USER_AUTH_SERVICE_LDAP},
+			Expected: []string{ldapUser.Id, inactiveLdapUser.Id},
+		},
+		"inactive ldap users": {
+			Filter:   model.UserFilter{AuthService: model.USER_AUTH_SERVICE_LDAP, Active: &inactive},
+			Expected: []string{inactiveLdapUser.Id},
+		},
+		"active ldap users that haven't been seen": {
+			Filter:   model.UserFilter{AuthService: model.USER_AUTH_SERVICE_LDAP, Active: &active, LastSeenBefore: beforeLogin},
+			Expected: []string{ldapUser.Id},
+		},
+		"recently seen users": {
+			Filter:   model.UserFilter{LastSeenAfter: beforeLogin},
+			Expected: []string{emailUser.Id},
+		},
+		"admins": {
+			Filter:   model.UserFilter{Role: model.SYSTEM_ADMIN_ROLE_ID},
+			Expected: []string{th.SystemAdminUser.Id},
+		},
+		"created": {
+			Filter:   model.UserFilter{CreatedAfter: ldapUser.CreateAt, CreatedBefore: emailUser.CreateAt, Active: &active},
+			Expected: []string{ldapUser.Id},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			filter := tc.Filter
+			filter.TeamId = team.Id
+
+			users, resp := th.SystemAdminClient.GetUsersWithFilter(&filter, 0, 60)
+			CheckNoError(t, resp)
+			ids := []string{}
+			for _, user := range users {
+				ids = append(ids, user.Id)
+			}
+			assert.ElementsMatch(t, tc.Expected, ids)
+
+			stats, resp := th.SystemAdminClient.GetUsersStats(&filter)
+			CheckNoError(t, resp)
+			assert.Equal(t, int64(len(tc.Expected)), stats.TotalUsersCount)
+		})
+	}
+
+	filter := &model.UserFilter{TeamId: team.Id, Active: &active}
+	stats, resp := th.SystemAdminClient.GetUsersStats(filter)
+	CheckNoError(t, resp)
+	assert.Equal(t, int64(3), stats.TotalUsersCount)
+
+	users, resp := th.SystemAdminClient.GetUsersWithFilter(filter, 0, 2)
+	CheckNoError(t, resp)
+	assert.Len(t, users, 2)
+	users, resp = th.SystemAdminClient.GetUsersWithFilter(filter, 1, 2)
+	CheckNoError(t, resp)
+	assert.Len(t, users, 1)
+
+	users, resp = th.SystemAdminClient.GetUsersWithFilter(&model.UserFilter{TeamId: team.Id, LastSeenAfter: beforeLogin}, 0, 60)
+	CheckNoError(t, resp)
+	require.Len(t, users, 1)
+	assert.True(t, users[0].LastSeenAt >= beforeLogin, "should show admins when users were last seen")
+
+	_, resp = Client.GetUsersWithFilter(filter, 0, 60)
+	CheckForbiddenStatus(t, resp)
+	_, resp = Client.GetUsersStats(filter)
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = th.SystemAdminClient.GetUsersWithFilter(&model.UserFilter{LastSeenAfter: 2000, LastSeenBefore: 1000}, 0, 60)
+	CheckBadRequestStatus(t, resp)
+
+	r, err := th.SystemAdminClient.DoApiGet("/users?active=maybe", "")
+	require.NotNil(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.StatusCode)
+	closeBody(r)
+
+	r, err = th.SystemAdminClient.DoApiGet("/users?role=system_admin&not_in_team="+team.Id, "")
+	require.NotNil(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.StatusCode, "should only filter all users or a team's users")
+	closeBody(r)
+
+	user, resp := Client.GetUser(emailUser.Id, "")
+	CheckNoError(t, resp)
+	assert.Equal(t, int64(0), user.LastSeenAt, "shouldn't show other users when a user was last seen")
+}
+
 func TestGetNewUsersInTeam(t *testing.T) {
 	th := Setup().InitBasic()
 	defer th.TearDown()
@@ -1984,6 +2330,68 @@ func TestRevokeAllSessions(t *testing.T) {
 	CheckUnauthorizedStatus(t, resp)
 }
 
+func TestImpersonateUser(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+
+	_, resp := th.SystemAdminClient.ImpersonateUser(th.BasicUser.Id)
+	CheckNotImplementedStatus(t, resp)
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableUserImpersonation = true })
+
+	_, resp = th.Client.ImpersonateUser(th.BasicUser2.Id)
+	CheckForbiddenStatus(t, resp)
+
+	session, resp := th.SystemAdminClient.ImpersonateUser(th.BasicUser.Id)
+	CheckNoError(t, resp)
+	CheckCreatedStatus(t, resp)
+	assert.True(t, session.IsImpersonation)
+	assert.Equal(t, th.SystemAdminUser.Id, session.Props[model.SESSION_PROP_IMPERSONATOR_ID])
+
+	client := th.CreateClient()
+	client.MockSession(session.Token)
+
+	me, resp := client.GetMe("")
+	CheckNoError(t, resp)
+	assert.Equal(t, th.BasicUser.Id, me.Id)
+
+	_, resp = client.ImpersonateUser(th.BasicUser2.Id)
+	CheckForbiddenStatus(t, resp)
+
+	t.Run("requests are audited", func(t *testing.T) {
+		audits, err := th.App.GetAudits(th.SystemAdminUser.Id, 100)
+		require.Nil(t, err)
+
+		found := false
+		for _, audit := range audits {
+			if audit.SessionId == session.Id && audit.Action == "/api/v4/users/me" {
+				found = true
+				assert.Contains(t, audit.ExtraInfo, "impersonated_user_id="+th.BasicUser.Id)
+				assert.Contains(t, audit.ExtraInfo, "method=GET")
+			}
+		}
+		assert.True(t, found, "should have audited the request made while impersonating")
+	})
+
+	t.Run("activity isn't recorded for the user", func(t *testing.T) {
+		before, _ := th.App.GetStatus(th.BasicUser.Id)
+
+		_, resp := client.CreatePost(&model.Post{ChannelId: th.BasicChannel.Id, Message: "impersonated"})
+		CheckNoError(t, resp)
+
+		after, _ := th.App.GetStatus(th.BasicUser.Id)
+		assert.Equal(t, before, after)
+	})
+
+	t.Run("revoked with the admin's session", func(t *testing.T) {
+		_, resp := th.SystemAdminClient.Logout()
+		CheckNoError(t, resp)
+
+		_, resp = client.GetMe("")
+		CheckUnauthorizedStatus(t, resp)
+	})
+}
+
 func TestAttachDeviceId(t *testing.T) {
 	th := Setup().InitBasic()
 	defer th.TearDown()
@@ -2063,6 +2471,105 @@ func TestVerifyUserEmail(t *testing.T) {
 	CheckBadRequestStatus(t, resp)
 }
 
+func TestEmailChange(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	user := th.CreateUser()
+	Client.Login(user.Email, user.Password)
+
+	newEmail := th.GenerateTestEmail()
+	ruser, resp := Client.PatchUser(user.Id, &model.UserPatch{Email: model.NewString(newEmail)})
+	CheckNoError(t, resp)
+	assert.Equal(t, user.Email, ruser.Email, "the email shouldn't change until it's verified")
+
+	_, resp = th.CreateClient().Login(newEmail, user.Password)
+	CheckBadRequestStatus(t, resp)
+	assert.Equal(t, "api.user.login.email_change_pending.app_error", resp.Error.Id)
+
+	getUndoToken := func() string {
+		result := <-th.App.Srv.Store.Token().GetAllTokensByType(app.TOKEN_TYPE_EMAIL_CHANGE_UNDO)
+		require.Nil(t, result.Err)
+		var undoToken string
+		for _, token := range result.Data.([]*model.Token) {
+			if strings.Contains(token.Extra, user.Id) {
+				undoToken = token.Token
+			}
+		}
+		require.NotEmpty(t, undoToken)
+		return undoToken
+	}
+	undoToken := getUndoToken()
+
+	_, resp = th.CreateClient().UndoEmailChange(GenerateTestId())
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = th.CreateClient().UndoEmailChange(undoToken)
+	CheckNoError(t, resp)
+
+	_, resp = Client.GetMe("")
+	CheckUnauthorizedStatus(t, resp)
+
+	_, resp = th.CreateClient().Login(newEmail, user.Password)
+	CheckBadRequestStatus(t, resp)
+	_, resp = Client.Login(user.Email, user.Password)
+	CheckNoError(t, resp)
+
+	// The link in the email to the old address asks the user to confirm before undoing the change
+	newEmail = th.GenerateTestEmail()
+	_, resp = Client.PatchUser(user.Id, &model.UserPatch{Email: model.NewString(newEmail)})
+	CheckNoError(t, resp)
+
+	httpClient := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	followUndoLink := func(token string) (*http.Response, string) {
+		r, err := httpClient.Get(Client.Url + app.EMAIL_CHANGE_UNDO_PATH + "?token=" + token)
+		require.Nil(t, err)
+		defer r.Body.Close()
+		body, err := ioutil.ReadAll(r.Body)
+		require.Nil(t, err)
+		return r, string(body)
+	}
+	submitUndoPage := func(token string) *http.Response {
+		r, err := httpClient.PostForm(Client.ApiUrl+"/users/email/undo_change", url.Values{"token": {token}})
+		require.Nil(t, err)
+		r.Body.Close()
+		return r
+	}
+
+	r, _ := followUndoLink(GenerateTestId())
+	assert.Equal(t, http.StatusBadRequest, r.StatusCode)
+
+	undoToken = getUndoToken()
+	r, body := followUndoLink(undoToken)
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+	assert.Contains(t, body, `<form method="post" action="`+Client.ApiUrl+`/users/email/undo_change">`)
+	assert.Contains(t, body, `value="`+undoToken+`"`)
+
+	_, resp = th.CreateClient().Login(newEmail, user.Password)
+	CheckBadRequestStatus(t, resp)
+	assert.Equal(t, "api.user.login.email_change_pending.app_error", resp.Error.Id, "opening the link shouldn't undo the change")
+	_, resp = Client.GetMe("")
+	CheckNoError(t, resp)
+
+	assert.Equal(t, http.StatusBadRequest, submitUndoPage(GenerateTestId()).StatusCode)
+
+	r = submitUndoPage(undoToken)
+	assert.Equal(t, http.StatusFound, r.StatusCode)
+	assert.Contains(t, r.Header.Get("Location"), "/login")
+
+	_, resp = th.CreateClient().Login(newEmail, user.Password)
+	CheckBadRequestStatus(t, resp)
+	assert.NotEqual(t, "api.user.login.email_change_pending.app_error", resp.Error.Id, "the change should be cancelled")
+
+	adminEmail := th.GenerateTestEmail()
+	ruser, resp = th.SystemAdminClient.PatchUser(user.Id, &model.UserPatch{Email: model.NewString(adminEmail)})
+	CheckNoError(t, resp)
+	assert.Equal(t, adminEmail, ruser.Email, "admins can change emails without verification")
+}
+
 func TestSendVerificationEmail(t *testing.T) {
 	th := Setup().InitBasic()
 	defer th.TearDown()
@@ -2800,3 +3307,342 @@ func TestGetUsersByStatus(t *testing.T) {
 		}
 	})
 }
+
+func TestGetUsernamePolicyViolations(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.UsernamePolicySettings.ReservedUsernames = th.BasicUser2.Username
+	})
+
+	_, resp := th.Client.GetUsernamePolicyViolations()
+	CheckForbiddenStatus(t, resp)
+
+	violations, resp := th.SystemAdminClient.GetUsernamePolicyViolations()
+	CheckNoError(t, resp)
+	require.Len(t, violations, 1)
+	assert.Equal(t, th.BasicUser2.Id, violations[0].UserId)
+	assert.Equal(t, model.USERNAME_POLICY_VIOLATION_RESERVED, violations[0].Violation)
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.UsernamePolicySettings.Enable = true
+	})
+
+	_, resp = th.Client.PatchUser(th.BasicUser.Id, &model.UserPatch{Username: model.NewString(th.BasicUser2.Username)})
+	CheckBadRequestStatus(t, resp)
+	CheckErrorMessage(t, resp, "app.user.username_policy.reserved.app_error")
+}
+
+func TestNotificationTrace(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	defer th.App.SetNotificationTrace(th.BasicUser2.Id, 0)
+
+	_, resp := Client.SetNotificationTrace(th.BasicUser2.Id, 60)
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = Client.GetNotificationTraceStatus(th.BasicUser2.Id)
+	CheckForbiddenStatus(t, resp)
+
+	_, resp = th.SystemAdminClient.SetNotificationTrace(th.BasicUser2.Id, model.NOTIFICATION_TRACE_MAX_TTL_SECONDS+1)
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = th.SystemAdminClient.SetNotificationTrace(model.NewId(), 60)
+	CheckNotFoundStatus(t, resp)
+
+	status, resp := th.SystemAdminClient.SetNotificationTrace(th.BasicUser2.Id, 60)
+	CheckNoError(t, resp)
+	assert.Equal(t, th.BasicUser2.Id, status.UserId)
+	assert.True(t, status.ExpiresAt > model.GetMillis())
+
+	status, resp = th.SystemAdminClient.GetNotificationTraceStatus(th.BasicUser2.Id)
+	CheckNoError(t, resp)
+	assert.True(t, status.ExpiresAt > model.GetMillis())
+
+	post, resp := Client.CreatePost(&model.Post{ChannelId: th.BasicChannel.Id, Message: "@" + th.BasicUser2.Username + " hello"})
+	CheckNoError(t, resp)
+
+	_, resp = Client.GetNotificationTrace(th.BasicUser2.Id, post.Id)
+	CheckForbiddenStatus(t, resp)
+
+	var trace *model.NotificationTrace
+	for i := 0; i < 20 && trace == nil; i++ {
+		time.Sleep(50 * time.Millisecond)
+		trace, resp = th.SystemAdminClient.GetNotificationTrace(th.BasicUser2.Id, post.Id)
+	}
+	CheckNoError(t, resp)
+	require.NotNil(t, trace)
+	assert.Equal(t, post.Id, trace.PostId)
+	assert.Equal(t, th.BasicUser2.Id, trace.UserId)
+	assert.NotEmpty(t, trace.Steps)
+
+	_, resp = th.SystemAdminClient.GetNotificationTrace(th.BasicUser.Id, post.Id)
+	CheckNotFoundStatus(t, resp)
+
+	status, resp = th.SystemAdminClient.SetNotificationTrace(th.BasicUser2.Id, 0)
+	CheckNoError(t, resp)
+	assert.Zero(t, status.ExpiresAt)
+}
+
+func TestPublishUserTyping(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+	Client := th.Client
+
+	connect := func(user *model.User) *model.WebSocketClient {
+		client := th.CreateClient()
+		_, resp := client.Login(user.Email, user.Password)
+		CheckNoError(t, resp)
+
+		wsClient, err := model.NewWebSocketClient4(fmt.Sprintf("ws://localhost:%v", th.App.Srv.ListenAddr.Port), client.AuthToken)
+		require.Nil(t, err)
+		wsClient.Listen()
+
+		resp2 := <-wsClient.ResponseChannel
+		require.Equal(t, model.STATUS_OK, resp2.Status)
+
+		return wsClient
+	}
+
+	receiveTyping := func(wsClient *model.WebSocketClient, timeout time.Duration) *model.WebSocketEvent {
+		for {
+			select {
+			case event := <-wsClient.EventChannel:
+				if event.Event == model.WEBSOCKET_EVENT_TYPING {
+					return event
+				}
+			case <-time.After(timeout):
+				return nil
+			}
+		}
+	}
+
+	root := th.BasicPost
+	reply, resp := Client.CreatePost(&model.Post{ChannelId: root.ChannelId, RootId: root.Id, Message: "reply"})
+	CheckNoError(t, resp)
+
+	_, err := th.App.UpdateThreadFollowForUser(th.BasicUser2.Id, th.BasicTeam.Id, root.Id, true)
+	require.Nil(t, err)
+
+	channelViewer := th.CreateUser()
+	th.LinkUserToTeam(channelViewer, th.BasicTeam)
+	th.AddUserToChannel(channelViewer, th.BasicChannel)
+
+	threadViewer := th.CreateUser()
+	th.LinkUserToTeam(threadViewer, th.BasicTeam)
+	th.AddUserToChannel(threadViewer, th.BasicChannel)
+
+	followerWs := connect(th.BasicUser2)
+	defer followerWs.Close()
+	channelViewerWs := connect(channelViewer)
+	defer channelViewerWs.Close()
+	threadViewerWs := connect(threadViewer)
+	defer threadViewerWs.Close()
+
+	threadViewerWs.ViewThread(root.Id)
+	require.Equal(t, model.STATUS_OK, (<-threadViewerWs.ResponseChannel).Status)
+
+	t.Run("thread typing is only sent to followers and viewers", func(t *testing.T) {
+		ok, resp := Client.PublishUserTyping(th.BasicUser.Id, model.TypingRequest{ChannelId: th.BasicChannel.Id, ParentId: reply.Id})
+		CheckNoError(t, resp)
+		assert.True(t, ok)
+
+		event := receiveTyping(followerWs, 2*time.Second)
+		require.NotNil(t, event)
+		assert.Equal(t, th.BasicUser.Id, event.Data["user_id"])
+		assert.Equal(t, reply.Id, event.Data["parent_id"])
+		assert.Equal(t, root.Id, event.Data["root_id"])
+
+		assert.NotNil(t, receiveTyping(threadViewerWs, 2*time.Second))
+		assert.Nil(t, receiveTyping(channelViewerWs, 300*time.Millisecond))
+	})
+
+	t.Run("thread typing is throttled", func(t *testing.T) {
+		_, resp := Client.PublishUserTyping(th.BasicUser.Id, model.TypingRequest{ChannelId: th.BasicChannel.Id, ParentId: root.Id})
+		CheckNoError(t, resp)
+
+		assert.Nil(t, receiveTyping(followerWs, 300*time.Millisecond))
+	})
+
+	t.Run("channel typing is sent to the whole channel", func(t *testing.T) {
+		_, resp := Client.PublishUserTyping(th.BasicUser.Id, model.TypingRequest{ChannelId: th.BasicChannel.Id})
+		CheckNoError(t, resp)
+
+		event := receiveTyping(channelViewerWs, 2*time.Second)
+		require.NotNil(t, event)
+		assert.Equal(t, "", event.Data["parent_id"])
+		assert.Nil(t, event.Data["root_id"])
+
+		assert.NotNil(t, receiveTyping(followerWs, 2*time.Second))
+	})
+
+	t.Run("closing the thread stops thread typing", func(t *testing.T) {
+		threadViewerWs.ViewThread("")
+		require.Equal(t, model.STATUS_OK, (<-threadViewerWs.ResponseChannel).Status)
+		receiveTyping(threadViewerWs, 300*time.Millisecond)
+
+		interval := *th.App.Config().ServiceSettings.TimeBetweenUserTypingUpdatesMilliseconds
+		defer th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.TimeBetweenUserTypingUpdatesMilliseconds = interval })
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.TimeBetweenUserTypingUpdatesMilliseconds = 1000 })
+		time.Sleep(600 * time.Millisecond)
+
+		_, resp := Client.PublishUserTyping(th.BasicUser.Id, model.TypingRequest{ChannelId: th.BasicChannel.Id, ParentId: root.Id})
+		CheckNoError(t, resp)
+
+		assert.NotNil(t, receiveTyping(followerWs, 2*time.Second))
+		assert.Nil(t, receiveTyping(threadViewerWs, 300*time.Millisecond))
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		_, resp := Client.PublishUserTyping(th.BasicUser.Id, model.TypingRequest{ChannelId: "junk"})
+		CheckBadRequestStatus(t, resp)
+
+		_, resp = Client.PublishUserTyping(th.BasicUser.Id, model.TypingRequest{ChannelId: th.BasicChannel2.Id, ParentId: root.Id})
+		CheckBadRequestStatus(t, resp)
+
+		_, resp = Client.PublishUserTyping(th.BasicUser2.Id, model.TypingRequest{ChannelId: th.BasicChannel.Id})
+		CheckForbiddenStatus(t, resp)
+
+		_, resp = Client.PublishUserTyping(th.BasicUser.Id, model.TypingRequest{ChannelId: model.NewId()})
+		CheckForbiddenStatus(t, resp)
+
+		Client.Logout()
+		_, resp = Client.PublishUserTyping(th.BasicUser.Id, model.TypingRequest{ChannelId: th.BasicChannel.Id})
+		CheckUnauthorizedStatus(t, resp)
+	})
+}
+
+func TestBlockUser(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+	Client := th.Client
+
+	user3 := th.CreateUser()
+	th.LinkUserToTeam(user3, th.BasicTeam)
+
+	ok, resp := Client.BlockUser(th.BasicUser.Id, user3.Id)
+	CheckNoError(t, resp)
+	assert.True(t, ok)
+
+	_, resp = Client.BlockUser(th.BasicUser.Id, th.BasicUser.Id)
+	CheckBadRequestStatus(t, resp)
+
+	_, resp = Client.BlockUser(th.BasicUser2.Id, user3.Id)
+	CheckForbiddenStatus(t, resp)
+
+	users, resp := Client.GetBlockedUsers(th.BasicUser.Id)
+	CheckNoError(t, resp)
+	require.Len(t, users, 1)
+	assert.Equal(t, user3.Id, users[0].Id)
+
+	_, resp = Client.GetBlockedUsers(th.BasicUser2.Id)
+	CheckForbiddenStatus(t, resp)
+
+	t.Run("is_blocked is only shown to the user that blocked them", func(t *testing.T) {
+		user, resp := Client.GetUser(user3.Id, "")
+		CheckNoError(t, resp)
+		assert.True(t, user.IsBlocked)
+
+		users, resp := Client.GetUsersByIds([]string{user3.Id, th.BasicUser2.Id})
+		CheckNoError(t, resp)
+		for _, user := range users {
+			assert.Equal(t, user.Id == user3.Id, user.IsBlocked)
+		}
+
+		client2 := th.CreateClient()
+		_, resp = client2.Login(th.BasicUser2.Email, th.BasicUser2.Password)
+		CheckNoError(t, resp)
+
+		user, resp = client2.GetUser(user3.Id, "")
+		CheckNoError(t, resp)
+		assert.False(t, user.IsBlocked)
+	})
+
+	t.Run("direct messages can't be started", func(t *testing.T) {
+		_, resp := Client.CreateDirectChannel(th.BasicUser.Id, user3.Id)
+		CheckForbiddenStatus(t, resp)
+
+		client3 := th.CreateClient()
+		_, resp = client3.Login(user3.Email, user3.Password)
+		CheckNoError(t, resp)
+
+		_, resp = client3.CreateDirectChannel(user3.Id, th.BasicUser.Id)
+		CheckForbiddenStatus(t, resp)
+	})
+
+	t.Run("unblocking allows direct messages again", func(t *testing.T) {
+		ok, resp := Client.UnblockUser(th.BasicUser.Id, user3.Id)
+		CheckNoError(t, resp)
+		assert.True(t, ok)
+
+		users, resp := Client.GetBlockedUsers(th.BasicUser.Id)
+		CheckNoError(t, resp)
+		assert.Len(t, users, 0)
+
+		_, resp = Client.CreateDirectChannel(th.BasicUser.Id, user3.Id)
+		CheckNoError(t, resp)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableUserBlocking = false })
+		defer th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableUserBlocking = true })
+
+		_, resp := Client.BlockUser(th.BasicUser.Id, user3.Id)
+		CheckNotImplementedStatus(t, resp)
+
+		_, resp = Client.GetBlockedUsers(th.BasicUser.Id)
+		CheckNotImplementedStatus(t, resp)
+	})
+}
+
+func TestLoginCaptcha(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+	Client := th.Client
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("secret") == "secret" && r.FormValue("response") == "solved" {
+			w.Write([]byte(`{"success": true}`))
+		} else {
+			w.Write([]byte(`{"success": false}`))
+		}
+	}))
+	defer server.Close()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.CaptchaSettings.Enable = true
+		*cfg.CaptchaSettings.SiteKey = "site"
+		*cfg.CaptchaSettings.SecretKey = "secret"
+		*cfg.CaptchaSettings.VerifyURL = server.URL
+		*cfg.CaptchaSettings.LoginFailuresPerUser = 2
+	})
+
+	assert.Equal(t, "true", th.App.ClientConfig()["EnableLoginCaptcha"])
+	assert.Equal(t, "site", th.App.ClientConfig()["CaptchaSiteKey"])
+
+	user := th.BasicUser
+	Client.Logout()
+
+	for i := 0; i < 2; i++ {
+		_, resp := Client.Login(user.Email, "wrong")
+		CheckErrorMessage(t, resp, "api.user.check_user_password.invalid.app_error")
+	}
+
+	_, resp := Client.Login(user.Email, user.Password)
+	CheckErrorMessage(t, resp, "api.user.login.captcha_required.app_error")
+	CheckUnauthorizedStatus(t, resp)
+
+	_, resp = Client.LoginWithCaptcha(user.Email, user.Password, "unsolved")
+	CheckErrorMessage(t, resp, "api.user.login.captcha_invalid.app_error")
+
+	_, resp = Client.LoginWithCaptcha(user.Email, user.Password, "solved")
+	CheckNoError(t, resp)
+
+	Client.Logout()
+
+	_, resp = Client.Login(user.Email, user.Password)
+	CheckNoError(t, resp)
+}
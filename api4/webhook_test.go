@@ -4,9 +4,15 @@
 package api4
 
 import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/mattermost/mattermost-server/model"
 )
@@ -675,6 +681,100 @@ func TestRegenOutgoingHookToken(t *testing.T) {
 	CheckNotImplementedStatus(t, resp)
 }
 
+func TestRegenOutgoingHookSigningSecret(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+	Client := th.Client
+
+	th.App.UpdateConfig(func(cfg *model.Config) { cfg.ServiceSettings.EnableOutgoingWebhooks = true })
+
+	hook := &model.OutgoingWebhook{ChannelId: th.BasicChannel.Id, TeamId: th.BasicChannel.TeamId, CallbackURLs: []string{"http://nowhere.com"}}
+	rhook, resp := th.SystemAdminClient.CreateOutgoingWebhook(hook)
+	CheckNoError(t, resp)
+	require.NotEmpty(t, rhook.SigningSecret, "new hooks should be signed")
+
+	regenHook, resp := th.SystemAdminClient.RegenOutgoingHookSigningSecret(rhook.Id)
+	CheckNoError(t, resp)
+	assert.NotEqual(t, rhook.SigningSecret, regenHook.SigningSecret)
+	assert.Equal(t, rhook.Token, regenHook.Token)
+
+	rhook.SigningSecret = "chosen"
+	updatedHook, resp := th.SystemAdminClient.UpdateOutgoingWebhook(rhook)
+	CheckNoError(t, resp)
+	assert.Equal(t, regenHook.SigningSecret, updatedHook.SigningSecret, "the secret shouldn't be changed by updates")
+
+	_, resp = Client.RegenOutgoingHookSigningSecret(rhook.Id)
+	CheckForbiddenStatus(t, resp)
+
+	th.App.UpdateConfig(func(cfg *model.Config) { cfg.ServiceSettings.EnableOutgoingWebhooks = false })
+	_, resp = th.SystemAdminClient.RegenOutgoingHookSigningSecret(rhook.Id)
+	CheckNotImplementedStatus(t, resp)
+}
+
+func TestOutgoingHookSigning(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		cfg.ServiceSettings.EnableOutgoingWebhooks = true
+		*cfg.ServiceSettings.AllowedUntrustedInternalConnections = "127.0.0.0/8"
+	})
+
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	requests := make(chan received, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests <- received{r.Header, body}
+	}))
+	defer ts.Close()
+
+	hook, resp := th.SystemAdminClient.CreateOutgoingWebhook(&model.OutgoingWebhook{
+		ChannelId:          th.BasicChannel.Id,
+		TeamId:             th.BasicChannel.TeamId,
+		CallbackURLs:       []string{ts.URL},
+		TriggerWords:       []string{"signed"},
+		SigningAlgorithm:   model.INTEGRATION_SIGNING_ALGORITHM_SHA512,
+		DisableLegacyToken: true,
+	})
+	CheckNoError(t, resp)
+
+	trigger := func() received {
+		_, resp := th.Client.CreatePost(&model.Post{ChannelId: th.BasicChannel.Id, Message: "signed message"})
+		CheckNoError(t, resp)
+
+		select {
+		case request := <-requests:
+			return request
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "timed out waiting for the outgoing webhook")
+			return received{}
+		}
+	}
+
+	request := trigger()
+	assert.Nil(t, model.VerifyIntegrationRequest(request.header, request.body, hook.SigningSecret, model.GetMillis(), model.INTEGRATION_SIGNING_DEFAULT_TOLERANCE))
+	assert.Contains(t, request.header.Get(model.HEADER_INTEGRATION_SIGNATURE), "sha512=")
+
+	values, err := url.ParseQuery(string(request.body))
+	require.Nil(t, err)
+	assert.Equal(t, "signed message", values.Get("text"))
+	assert.NotContains(t, values, "token", "the token should be left out")
+
+	hook.DisableLegacyToken = false
+	_, resp = th.SystemAdminClient.UpdateOutgoingWebhook(hook)
+	CheckNoError(t, resp)
+
+	request = trigger()
+	assert.Nil(t, model.VerifyIntegrationRequest(request.header, request.body, hook.SigningSecret, model.GetMillis(), model.INTEGRATION_SIGNING_DEFAULT_TOLERANCE))
+
+	values, err = url.ParseQuery(string(request.body))
+	require.Nil(t, err)
+	assert.Equal(t, hook.Token, values.Get("token"), "the token should still be sent for compatibility")
+}
+
 func TestUpdateOutgoingHook(t *testing.T) {
 	th := Setup().InitBasic().InitSystemAdmin()
 	defer th.TearDown()
@@ -8,7 +8,6 @@ import (
 	"net/http"
 
 	"github.com/gorilla/websocket"
-	"github.com/mattermost/mattermost-server/mlog"
 	"github.com/mattermost/mattermost-server/model"
 )
 
@@ -17,6 +16,22 @@ func (api *API) InitWebSocket() {
 }
 
 func connectWebSocket(c *Context, w http.ResponseWriter, r *http.Request) {
+	// A draining server is about to be stopped, so the load balancer should send the connection to another one
+	if c.App.IsDraining() {
+		w.Header().Set(model.HEADER_DRAINING, "true")
+		w.Header().Set("Retry-After", "0")
+		c.Err = model.NewAppError("connect", "api.web_socket.connect.draining.app_error", nil, "", http.StatusServiceUnavailable)
+		return
+	}
+
+	// Connections that haven't authenticated yet are checked once they do
+	if len(c.Session.UserId) > 0 {
+		if err := c.App.CheckMaintenanceMode(c.Session); err != nil {
+			c.Err = err
+			return
+		}
+	}
+
 	upgrader := websocket.Upgrader{
 		ReadBufferSize:  model.SOCKET_MAX_MESSAGE_SIZE_KB,
 		WriteBufferSize: model.SOCKET_MAX_MESSAGE_SIZE_KB,
@@ -25,12 +40,14 @@ func connectWebSocket(c *Context, w http.ResponseWriter, r *http.Request) {
 
 	ws, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		mlog.Error(fmt.Sprintf("websocket connect err: %v", err))
+		c.Log.Error(fmt.Sprintf("websocket connect err: %v", err))
 		c.Err = model.NewAppError("connect", "api.web_socket.connect.upgrade.app_error", nil, "", http.StatusInternalServerError)
 		return
 	}
 
 	wc := c.App.NewWebConn(ws, c.Session, c.T, "")
+	wc.IpAddress = c.App.GetIpAddress(r)
+	wc.UserAgent = r.UserAgent()
 
 	if len(c.Session.UserId) > 0 {
 		c.App.HubRegister(wc)
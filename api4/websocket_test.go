@@ -458,3 +458,57 @@ func TestWebSocketStatuses(t *testing.T) {
 
 	WebSocketClient.Close()
 }
+
+func TestWebSocketPresenceSubscriptions(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	enabled := *th.App.Config().ServiceSettings.EnablePresenceSubscriptions
+	defer th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnablePresenceSubscriptions = enabled })
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnablePresenceSubscriptions = true })
+
+	WebSocketClient, err := th.CreateWebSocketClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer WebSocketClient.Close()
+	WebSocketClient.Listen()
+
+	if resp := <-WebSocketClient.ResponseChannel; resp.Status != model.STATUS_OK {
+		t.Fatal("should have responded OK to authentication challenge")
+	}
+
+	WebSocketClient.SubscribeToStatuses([]string{th.BasicUser2.Id})
+	if resp := <-WebSocketClient.ResponseChannel; resp.Error != nil {
+		t.Fatal(resp.Error)
+	} else if _, ok := resp.Data[th.BasicUser2.Id]; !ok {
+		t.Fatal("should have returned the user's status")
+	}
+
+	th.App.SetStatusDoNotDisturb(th.BasicUser2.Id)
+
+	timeout := time.After(5 * time.Second)
+	for received := false; !received; {
+		select {
+		case event := <-WebSocketClient.EventChannel:
+			if event.Event == model.WEBSOCKET_EVENT_STATUS_CHANGE && event.Data["user_id"] == th.BasicUser2.Id {
+				if event.Data["status"] != model.STATUS_DND {
+					t.Fatal("wrong status")
+				}
+				received = true
+			}
+		case <-timeout:
+			t.Fatal("should have received the status change of the subscribed user")
+		}
+	}
+
+	WebSocketClient.SubscribeToStatuses([]string{"junk"})
+	if resp := <-WebSocketClient.ResponseChannel; resp.Error == nil {
+		t.Fatal("should have errored - invalid user id")
+	}
+
+	WebSocketClient.UnsubscribeFromStatuses(nil)
+	if resp := <-WebSocketClient.ResponseChannel; resp.Error != nil {
+		t.Fatal(resp.Error)
+	}
+}
@@ -145,6 +145,7 @@ func (a *App) InvalidateAllCachesSkipSend() {
 	a.Srv.Store.Post().ClearCaches()
 	a.Srv.Store.FileInfo().ClearCaches()
 	a.Srv.Store.Webhook().ClearCaches()
+	a.InvalidateLinkBlocklistSkipClusterSend()
 	a.LoadLicense()
 }
 
@@ -161,6 +162,58 @@ func (a *App) GetEnvironmentConfig() map[string]interface{} {
 }
 
 func (a *App) SaveConfig(cfg *model.Config, sendConfigChangeClusterMessage bool) *model.AppError {
+	return a.SaveConfigAs(cfg, sendConfigChangeClusterMessage, "", model.CONFIGURATION_HISTORY_SOURCE_SERVER)
+}
+
+// SaveConfigAs saves the config like SaveConfig, recording it as a new version of the config made by the user, if
+// there is one, through the given source.
+func (a *App) SaveConfigAs(cfg *model.Config, sendConfigChangeClusterMessage bool, userId string, source string) *model.AppError {
+	a.configSaveLock.Lock()
+	defer a.configSaveLock.Unlock()
+
+	return a.saveConfig(cfg, sendConfigChangeClusterMessage, userId, source)
+}
+
+// SaveConfigFromVersion saves the config like SaveConfigAs as long as its ConfigVersion is the current one. A config
+// based on an older version would undo whatever was saved since, so it fails with http.StatusConflict instead.
+func (a *App) SaveConfigFromVersion(cfg *model.Config, sendConfigChangeClusterMessage bool, userId string, source string) *model.AppError {
+	a.configSaveLock.Lock()
+	defer a.configSaveLock.Unlock()
+
+	if cfg.ConfigVersion == nil {
+		return model.NewAppError("SaveConfigFromVersion", "app.admin.save_config.version_required.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if current := *a.Config().ConfigVersion; *cfg.ConfigVersion != current {
+		return model.NewAppError("SaveConfigFromVersion", "app.admin.save_config.version_conflict.app_error", map[string]interface{}{"Version": *cfg.ConfigVersion, "CurrentVersion": current}, "", http.StatusConflict)
+	}
+
+	return a.saveConfig(cfg, sendConfigChangeClusterMessage, userId, source)
+}
+
+// PatchConfig applies the settings in data onto the current config and saves it like SaveConfigAs, so the settings
+// that data doesn't have are left as they are, even if they were changed after the patch was made. As with saves
+// through the API, plugin uploads can't be toggled by a patch.
+func (a *App) PatchConfig(data io.Reader, userId string, source string) (*model.Config, *model.AppError) {
+	a.configSaveLock.Lock()
+	defer a.configSaveLock.Unlock()
+
+	cfg, err := model.MergeConfigJson(a.Config(), data)
+	if err != nil {
+		return nil, err
+	}
+	cfg.PluginSettings.EnableUploads = a.Config().PluginSettings.EnableUploads
+
+	if err := a.saveConfig(cfg, true, userId, source); err != nil {
+		return nil, err
+	}
+
+	return a.GetConfig(), nil
+}
+
+// saveConfig saves the config as the next version of it. It needs configSaveLock to be held so that the version
+// that it's checked against can't change until it's saved.
+func (a *App) saveConfig(cfg *model.Config, sendConfigChangeClusterMessage bool, userId string, source string) *model.AppError {
 	oldCfg := a.Config()
 	cfg.SetDefaults()
 	a.Desanitize(cfg)
@@ -173,10 +226,16 @@ func (a *App) SaveConfig(cfg *model.Config, sendConfigChangeClusterMessage bool)
 		return err
 	}
 
+	if err := a.ValidateDisabledAPIEndpoints(cfg); err != nil {
+		return err
+	}
+
 	if *a.Config().ClusterSettings.Enable && *a.Config().ClusterSettings.ReadOnlyConfig {
 		return model.NewAppError("saveConfig", "ent.cluster.save_config.error", nil, "", http.StatusForbidden)
 	}
 
+	cfg.ConfigVersion = model.NewInt64(*oldCfg.ConfigVersion + 1)
+
 	a.DisableConfigWatch()
 	a.UpdateConfig(func(update *model.Config) {
 		*update = *cfg
@@ -185,6 +244,11 @@ func (a *App) SaveConfig(cfg *model.Config, sendConfigChangeClusterMessage bool)
 	a.ReloadConfig()
 	a.EnableConfigWatch()
 
+	// The config has already been saved, so it isn't undone if its version can't be
+	if _, err := a.RecordConfigHistory(oldCfg, cfg, userId, source); err != nil {
+		mlog.Error("Failed to save a version of the config", mlog.String("error", err.Error()))
+	}
+
 	if a.Metrics != nil {
 		if *a.Config().MetricsSettings.Enable {
 			a.Metrics.StartServer()
@@ -0,0 +1,142 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+func TestSaveConfigFromVersion(t *testing.T) {
+	th := Setup()
+	defer th.TearDown()
+
+	base := th.App.GetConfig()
+	version := *base.ConfigVersion
+
+	first := base.Clone()
+	first.TeamSettings.SiteName = "First"
+	require.Nil(t, th.App.SaveConfigFromVersion(first, false, "", model.CONFIGURATION_HISTORY_SOURCE_SERVER))
+	assert.Equal(t, version+1, *th.App.Config().ConfigVersion)
+
+	saved, _, err := utils.ReadConfigFile(th.App.ConfigFileName(), false)
+	require.NoError(t, err)
+	assert.Equal(t, version+1, *saved.ConfigVersion, "the version should be saved with the config")
+
+	// a save based on the version that the first one replaced would undo it
+	second := base.Clone()
+	second.TeamSettings.SiteName = "Second"
+	appErr := th.App.SaveConfigFromVersion(second, false, "", model.CONFIGURATION_HISTORY_SOURCE_SERVER)
+	require.NotNil(t, appErr)
+	assert.Equal(t, http.StatusConflict, appErr.StatusCode)
+	assert.Equal(t, "First", th.App.Config().TeamSettings.SiteName)
+
+	t.Run("concurrent saves", func(t *testing.T) {
+		base := th.App.GetConfig()
+
+		var wg sync.WaitGroup
+		errs := make([]*model.AppError, 5)
+		for i := range errs {
+			cfg := base.Clone()
+			cfg.TeamSettings.SiteName = "Concurrent " + model.NewId()
+
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs[i] = th.App.SaveConfigFromVersion(cfg, false, "", model.CONFIGURATION_HISTORY_SOURCE_SERVER)
+			}(i)
+		}
+		wg.Wait()
+
+		saves := 0
+		for _, err := range errs {
+			if err == nil {
+				saves++
+			} else {
+				assert.Equal(t, http.StatusConflict, err.StatusCode)
+			}
+		}
+		assert.Equal(t, 1, saves, "only one of the saves should win")
+		assert.Equal(t, *base.ConfigVersion+1, *th.App.Config().ConfigVersion)
+	})
+
+	t.Run("saves always move the version forward", func(t *testing.T) {
+		current := *th.App.Config().ConfigVersion
+
+		require.Nil(t, th.App.SaveConfig(base.Clone(), false))
+		assert.Equal(t, current+1, *th.App.Config().ConfigVersion)
+	})
+
+	t.Run("no version", func(t *testing.T) {
+		cfg := th.App.GetConfig()
+		cfg.ConfigVersion = nil
+
+		appErr := th.App.SaveConfigFromVersion(cfg, false, "", model.CONFIGURATION_HISTORY_SOURCE_SERVER)
+		require.NotNil(t, appErr)
+		assert.Equal(t, "app.admin.save_config.version_required.app_error", appErr.Id)
+	})
+}
+
+func TestPatchConfig(t *testing.T) {
+	th := Setup()
+	defer th.TearDown()
+
+	version := *th.App.Config().ConfigVersion
+	maxUsersPerTeam := *th.App.Config().TeamSettings.MaxUsersPerTeam
+
+	// two patches of different settings made at the same time both apply
+	var wg sync.WaitGroup
+	for _, patch := range []string{
+		`{"TeamSettings": {"SiteName": "Patched"}}`,
+		`{"ServiceSettings": {"MaximumLoginAttempts": 7}}`,
+	} {
+		wg.Add(1)
+		go func(patch string) {
+			defer wg.Done()
+			_, err := th.App.PatchConfig(strings.NewReader(patch), "", model.CONFIGURATION_HISTORY_SOURCE_SERVER)
+			assert.Nil(t, err)
+		}(patch)
+	}
+	wg.Wait()
+
+	assert.Equal(t, "Patched", th.App.Config().TeamSettings.SiteName)
+	assert.Equal(t, 7, *th.App.Config().ServiceSettings.MaximumLoginAttempts)
+	assert.Equal(t, maxUsersPerTeam, *th.App.Config().TeamSettings.MaxUsersPerTeam, "settings that weren't patched should be left alone")
+	assert.Equal(t, version+2, *th.App.Config().ConfigVersion)
+
+	t.Run("a patch doesn't need the current version", func(t *testing.T) {
+		cfg, err := th.App.PatchConfig(strings.NewReader(`{"TeamSettings": {"MaxUsersPerTeam": 42}, "ConfigVersion": 0}`), "", model.CONFIGURATION_HISTORY_SOURCE_SERVER)
+		require.Nil(t, err)
+		assert.Equal(t, 42, *cfg.TeamSettings.MaxUsersPerTeam)
+		assert.Equal(t, "Patched", cfg.TeamSettings.SiteName)
+		assert.Equal(t, version+3, *cfg.ConfigVersion)
+	})
+
+	t.Run("plugin uploads can't be toggled", func(t *testing.T) {
+		enableUploads := *th.App.Config().PluginSettings.EnableUploads
+
+		_, err := th.App.PatchConfig(strings.NewReader(`{"PluginSettings": {"EnableUploads": `+strconv.FormatBool(!enableUploads)+`}}`), "", model.CONFIGURATION_HISTORY_SOURCE_SERVER)
+		require.Nil(t, err)
+		assert.Equal(t, enableUploads, *th.App.Config().PluginSettings.EnableUploads)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := th.App.PatchConfig(strings.NewReader(`{"TeamSettings": {"NotASetting": true}}`), "", model.CONFIGURATION_HISTORY_SOURCE_SERVER)
+		require.NotNil(t, err)
+		assert.Equal(t, "model.config.merge.unknown_setting.app_error", err.Id)
+
+		_, err = th.App.PatchConfig(strings.NewReader(`{"TeamSettings": {"MaxUsersPerTeam": 0}}`), "", model.CONFIGURATION_HISTORY_SOURCE_SERVER)
+		require.NotNil(t, err)
+		assert.Equal(t, 42, *th.App.Config().TeamSettings.MaxUsersPerTeam)
+	})
+}
@@ -5,10 +5,13 @@ package app
 
 import (
 	"fmt"
+	"net/http"
+	"time"
 
 	"github.com/mattermost/mattermost-server/mlog"
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/store"
+	"github.com/mattermost/mattermost-server/utils"
 )
 
 const (
@@ -152,6 +155,12 @@ func (a *App) GetAnalytics(name string, teamId string) (model.AnalyticsRows, *mo
 
 		return rows, nil
 	} else if name == "post_counts_day" {
+		if rows, ok, err := a.getRolledUpAnalyticsRows(teamId, model.ANALYTICS_METRIC_POSTS); err != nil {
+			return nil, err
+		} else if ok {
+			return rows, nil
+		}
+
 		if skipIntensiveQueries {
 			rows := model.AnalyticsRows{&model.AnalyticsRow{Name: "", Value: -1}}
 			return rows, nil
@@ -163,6 +172,12 @@ func (a *App) GetAnalytics(name string, teamId string) (model.AnalyticsRows, *mo
 			return r.Data.(model.AnalyticsRows), nil
 		}
 	} else if name == "user_counts_with_posts_day" {
+		if rows, ok, err := a.getRolledUpAnalyticsRows(teamId, model.ANALYTICS_METRIC_ACTIVE_USERS); err != nil {
+			return nil, err
+		} else if ok {
+			return rows, nil
+		}
+
 		if skipIntensiveQueries {
 			rows := model.AnalyticsRows{&model.AnalyticsRow{Name: "", Value: -1}}
 			return rows, nil
@@ -244,6 +259,199 @@ func (a *App) GetAnalytics(name string, teamId string) (model.AnalyticsRows, *mo
 	return nil, nil
 }
 
+// getRolledUpAnalyticsRows returns the daily values of the metric over the last month in the same form as the
+// queries that count them from the posts, if the daily rollups are up to date.
+// GetSeatReport returns the number of users taking up licensed seats along with the active member counts of every
+// team.
+func (a *App) GetSeatReport() (*model.SeatReport, *model.AppError) {
+	uchan := a.Srv.Store.User().AnalyticsUniqueUserCount("")
+	bchan := a.Srv.Store.User().AnalyticsBotCount()
+	dchan := a.Srv.Store.User().AnalyticsGetInactiveUsersCount()
+	tchan := a.Srv.Store.Team().GetAllMemberCounts()
+
+	report := &model.SeatReport{}
+
+	if result := <-uchan; result.Err != nil {
+		return nil, result.Err
+	} else {
+		report.ActiveUserCount = result.Data.(int64)
+	}
+
+	if result := <-bchan; result.Err != nil {
+		return nil, result.Err
+	} else {
+		report.BotCount = result.Data.(int64)
+	}
+
+	if result := <-dchan; result.Err != nil {
+		return nil, result.Err
+	} else {
+		report.DeactivatedUserCount = result.Data.(int64)
+	}
+
+	if result := <-tchan; result.Err != nil {
+		return nil, result.Err
+	} else {
+		report.Teams = result.Data.([]*model.TeamSeatCount)
+	}
+
+	return report, nil
+}
+
+func (a *App) getRolledUpAnalyticsRows(teamId string, metric string) (model.AnalyticsRows, bool, *model.AppError) {
+	yesterday := utils.Yesterday().Format(model.ANALYTICS_DAY_FORMAT)
+
+	if result := <-a.Srv.Store.AnalyticsDaily().GetLatestDay(); result.Err != nil {
+		return nil, false, result.Err
+	} else if result.Data.(string) < yesterday {
+		return nil, false, nil
+	}
+
+	from := utils.Yesterday().AddDate(0, 0, -31).Format(model.ANALYTICS_DAY_FORMAT)
+	result := <-a.Srv.Store.AnalyticsDaily().GetSeries(teamId, "", metric, from, yesterday)
+	if result.Err != nil {
+		return nil, false, result.Err
+	}
+	series := result.Data.([]*model.AnalyticsDaily)
+
+	rows := model.AnalyticsRows{}
+	for i := len(series) - 1; i >= 0 && len(rows) < 30; i-- {
+		if series[i].Value > 0 {
+			rows = append(rows, &model.AnalyticsRow{Name: series[i].Day, Value: float64(series[i].Value)})
+		}
+	}
+
+	return rows, true, nil
+}
+
+// GetAnalyticsSeries returns the value of the metric for each day from fromDay to toDay, inclusive, for the
+// channel or, if channelId is empty, the whole team. The days default to the 30 days up to yesterday.
+func (a *App) GetAnalyticsSeries(teamId string, channelId string, metric string, fromDay string, toDay string) (model.AnalyticsRows, *model.AppError) {
+	if !model.IsValidAnalyticsMetric(metric) {
+		return nil, model.NewAppError("GetAnalyticsSeries", "app.analytics.get_series.metric.app_error", map[string]interface{}{"Metric": metric}, "", http.StatusBadRequest)
+	}
+
+	from, to, err := parseAnalyticsDays(fromDay, toDay)
+	if err != nil {
+		return nil, err
+	}
+
+	result := <-a.Srv.Store.AnalyticsDaily().GetSeries(teamId, channelId, metric, from.Format(model.ANALYTICS_DAY_FORMAT), to.Format(model.ANALYTICS_DAY_FORMAT))
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	values := map[string]int64{}
+	for _, daily := range result.Data.([]*model.AnalyticsDaily) {
+		values[daily.Day] = daily.Value
+	}
+
+	rows := model.AnalyticsRows{}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		name := day.Format(model.ANALYTICS_DAY_FORMAT)
+		rows = append(rows, &model.AnalyticsRow{Name: name, Value: float64(values[name])})
+	}
+
+	return rows, nil
+}
+
+// parseAnalyticsDays parses the days of a range of rolled up analytics, which default to the 30 days up to
+// yesterday.
+func parseAnalyticsDays(fromDay string, toDay string) (time.Time, time.Time, *model.AppError) {
+	if toDay == "" {
+		toDay = utils.Yesterday().Format(model.ANALYTICS_DAY_FORMAT)
+	}
+
+	to, err := model.ParseAnalyticsDay(toDay, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	if fromDay == "" {
+		fromDay = to.AddDate(0, 0, -29).Format(model.ANALYTICS_DAY_FORMAT)
+	}
+
+	from, err := model.ParseAnalyticsDay(fromDay, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	if to.Before(from) || from.AddDate(0, 0, model.ANALYTICS_SERIES_MAX_DAYS).Before(to) {
+		return time.Time{}, time.Time{}, model.NewAppError("parseAnalyticsDays", "app.analytics.get_series.range.app_error", map[string]interface{}{"MaxDays": model.ANALYTICS_SERIES_MAX_DAYS}, "from="+fromDay+", to="+toDay, http.StatusBadRequest)
+	}
+
+	return from, to, nil
+}
+
+// GetUserAnalytics returns a page of the activity of each user from fromDay to toDay, inclusive, as rolled up
+// for each day, leaving out the users with less activity than AnalyticsSettings.MinimumUserActivity. The days
+// default to the 30 days up to yesterday.
+func (a *App) GetUserAnalytics(fromDay string, toDay string, page int, perPage int) ([]*model.AnalyticsUserActivity, *model.AppError) {
+	from, to, err := parseAnalyticsDays(fromDay, toDay)
+	if err != nil {
+		return nil, err
+	}
+
+	minActivity := int64(*a.Config().AnalyticsSettings.MinimumUserActivity)
+
+	result := <-a.Srv.Store.AnalyticsDaily().GetUserActivity(from.Format(model.ANALYTICS_DAY_FORMAT), to.Format(model.ANALYTICS_DAY_FORMAT), minActivity, page*perPage, perPage)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.([]*model.AnalyticsUserActivity), nil
+}
+
+// RollupAnalyticsDay replaces the daily metrics and user activity of the given day with ones computed from its
+// posts, reactions and memberships.
+func (a *App) RollupAnalyticsDay(day time.Time) *model.AppError {
+	start := utils.MillisFromTime(utils.StartOfDay(day))
+	end := utils.MillisFromTime(utils.EndOfDay(day))
+
+	if result := <-a.Srv.Store.AnalyticsDaily().RollupDay(day.Format(model.ANALYTICS_DAY_FORMAT), start, end); result.Err != nil {
+		return result.Err
+	}
+
+	return nil
+}
+
+// RollupAnalytics rolls up every day since the last one that was, up to and including yesterday, and deletes
+// the daily metrics and user activity that are older than AnalyticsSettings.DailyRetentionDays. It returns how
+// many days were rolled up.
+func (a *App) RollupAnalytics() (int, *model.AppError) {
+	retentionDays := *a.Config().AnalyticsSettings.DailyRetentionDays
+	yesterday := utils.StartOfDay(utils.Yesterday())
+	oldest := yesterday.AddDate(0, 0, 1-retentionDays)
+
+	day := oldest
+	if result := <-a.Srv.Store.AnalyticsDaily().GetLatestDay(); result.Err != nil {
+		return 0, result.Err
+	} else if latestDay := result.Data.(string); latestDay != "" {
+		latest, err := model.ParseAnalyticsDay(latestDay, time.Local)
+		if err != nil {
+			return 0, err
+		}
+
+		if next := latest.AddDate(0, 0, 1); next.After(oldest) {
+			day = next
+		}
+	}
+
+	rolledUp := 0
+	for ; !day.After(yesterday); day = day.AddDate(0, 0, 1) {
+		if err := a.RollupAnalyticsDay(day); err != nil {
+			return rolledUp, err
+		}
+		rolledUp++
+	}
+
+	if result := <-a.Srv.Store.AnalyticsDaily().PermanentDeleteBefore(oldest.Format(model.ANALYTICS_DAY_FORMAT)); result.Err != nil {
+		return rolledUp, result.Err
+	}
+
+	return rolledUp, nil
+}
+
 func (a *App) GetRecentlyActiveUsersForTeam(teamId string) (map[string]*model.User, *model.AppError) {
 	if result := <-a.Srv.Store.User().GetRecentlyActiveUsersForTeam(teamId, 0, 100); result.Err != nil {
 		return nil, result.Err
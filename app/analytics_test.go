@@ -0,0 +1,246 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+func TestAnalyticsSeries(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	channel := th.CreateChannel(th.BasicTeam)
+
+	yesterday := utils.StartOfDay(utils.Yesterday())
+	twoDaysAgo := yesterday.AddDate(0, 0, -1)
+	threeDaysAgo := yesterday.AddDate(0, 0, -2)
+
+	// two posts three days ago, none two days ago and three by two users yesterday
+	for _, post := range []*model.Post{
+		{UserId: th.BasicUser.Id, CreateAt: utils.MillisFromTime(threeDaysAgo) + 1000},
+		{UserId: th.BasicUser.Id, CreateAt: utils.MillisFromTime(threeDaysAgo) + 2000},
+		{UserId: th.BasicUser.Id, CreateAt: utils.MillisFromTime(yesterday) + 1000},
+		{UserId: th.BasicUser2.Id, CreateAt: utils.MillisFromTime(yesterday) + 2000},
+		{UserId: th.BasicUser2.Id, CreateAt: utils.MillisFromTime(yesterday) + 3000},
+	} {
+		post.ChannelId = channel.Id
+		post.Message = "message " + model.NewId()
+		store.Must(th.App.Srv.Store.Post().Save(post))
+	}
+
+	for _, day := range []int{-2, -1, 0} {
+		require.Nil(t, th.App.RollupAnalyticsDay(yesterday.AddDate(0, 0, day)))
+	}
+
+	from := threeDaysAgo.Format(model.ANALYTICS_DAY_FORMAT)
+	to := yesterday.Format(model.ANALYTICS_DAY_FORMAT)
+
+	t.Run("team posts", func(t *testing.T) {
+		rows, err := th.App.GetAnalyticsSeries(th.BasicTeam.Id, "", model.ANALYTICS_METRIC_POSTS, from, to)
+		require.Nil(t, err)
+		assert.Equal(t, model.AnalyticsRows{
+			{Name: from, Value: 2},
+			{Name: twoDaysAgo.Format(model.ANALYTICS_DAY_FORMAT), Value: 0},
+			{Name: to, Value: 3},
+		}, rows)
+	})
+
+	t.Run("channel active users", func(t *testing.T) {
+		rows, err := th.App.GetAnalyticsSeries(th.BasicTeam.Id, channel.Id, model.ANALYTICS_METRIC_ACTIVE_USERS, from, to)
+		require.Nil(t, err)
+		require.Len(t, rows, 3)
+		assert.Equal(t, float64(1), rows[0].Value)
+		assert.Equal(t, float64(0), rows[1].Value)
+		assert.Equal(t, float64(2), rows[2].Value)
+	})
+
+	t.Run("defaults to the last 30 days", func(t *testing.T) {
+		rows, err := th.App.GetAnalyticsSeries(th.BasicTeam.Id, "", model.ANALYTICS_METRIC_POSTS, "", "")
+		require.Nil(t, err)
+		require.Len(t, rows, 30)
+		assert.Equal(t, to, rows[29].Name)
+		assert.Equal(t, float64(3), rows[29].Value)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := th.App.GetAnalyticsSeries(th.BasicTeam.Id, "", "junk", from, to)
+		assert.NotNil(t, err)
+
+		_, err = th.App.GetAnalyticsSeries(th.BasicTeam.Id, "", model.ANALYTICS_METRIC_POSTS, "yesterday", to)
+		assert.NotNil(t, err)
+
+		_, err = th.App.GetAnalyticsSeries(th.BasicTeam.Id, "", model.ANALYTICS_METRIC_POSTS, to, from)
+		assert.NotNil(t, err)
+
+		_, err = th.App.GetAnalyticsSeries(th.BasicTeam.Id, "", model.ANALYTICS_METRIC_POSTS, "2000-01-01", to)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("system console reads the rollups", func(t *testing.T) {
+		rows, err := th.App.GetAnalytics("post_counts_day", th.BasicTeam.Id)
+		require.Nil(t, err)
+		assert.Equal(t, model.AnalyticsRows{
+			{Name: to, Value: 3},
+			{Name: from, Value: 2},
+		}, rows)
+
+		rows, err = th.App.GetAnalytics("user_counts_with_posts_day", th.BasicTeam.Id)
+		require.Nil(t, err)
+		assert.Equal(t, model.AnalyticsRows{
+			{Name: to, Value: 2},
+			{Name: from, Value: 1},
+		}, rows)
+	})
+}
+
+func TestRollupAnalytics(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.AnalyticsSettings.DailyRetentionDays = 3 })
+
+	yesterday := utils.StartOfDay(utils.Yesterday())
+	expired := yesterday.AddDate(0, 0, -10)
+	require.Nil(t, th.App.RollupAnalyticsDay(expired))
+
+	_, err := th.App.RollupAnalytics()
+	require.Nil(t, err)
+
+	result := <-th.App.Srv.Store.AnalyticsDaily().GetLatestDay()
+	require.Nil(t, result.Err)
+	assert.Equal(t, yesterday.Format(model.ANALYTICS_DAY_FORMAT), result.Data.(string))
+
+	// the days older than the retention period are deleted
+	expiredDay := expired.Format(model.ANALYTICS_DAY_FORMAT)
+	result = <-th.App.Srv.Store.AnalyticsDaily().GetSeries("", "", model.ANALYTICS_METRIC_POSTS, expiredDay, expiredDay)
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]*model.AnalyticsDaily), 0)
+
+	// and there is nothing left to roll up
+	rolledUp, err := th.App.RollupAnalytics()
+	require.Nil(t, err)
+	assert.Equal(t, 0, rolledUp)
+}
+
+func TestGetUserAnalytics(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	yesterday := utils.StartOfDay(utils.Yesterday())
+	start := utils.MillisFromTime(yesterday)
+
+	root := store.Must(th.App.Srv.Store.Post().Save(&model.Post{UserId: th.BasicUser.Id, ChannelId: th.BasicChannel.Id, Message: "message", CreateAt: start + 1000})).(*model.Post)
+	store.Must(th.App.Srv.Store.Post().Save(&model.Post{UserId: th.BasicUser.Id, ChannelId: th.BasicChannel.Id, RootId: root.Id, ParentId: root.Id, Message: "reply", CreateAt: start + 2000}))
+	store.Must(th.App.Srv.Store.Reaction().Save(&model.Reaction{UserId: th.BasicUser2.Id, PostId: root.Id, EmojiName: "smile", CreateAt: start + 3000}))
+
+	require.Nil(t, th.App.RollupAnalyticsDay(yesterday))
+
+	getActivity := func() map[string]*model.AnalyticsUserActivity {
+		activity, err := th.App.GetUserAnalytics("", "", 0, 1000)
+		require.Nil(t, err)
+
+		byUser := map[string]*model.AnalyticsUserActivity{}
+		for _, user := range activity {
+			byUser[user.UserId] = user
+		}
+		return byUser
+	}
+
+	activity := getActivity()
+	require.NotNil(t, activity[th.BasicUser.Id])
+	assert.Equal(t, &model.AnalyticsUserActivity{
+		UserId:         th.BasicUser.Id,
+		Username:       th.BasicUser.Username,
+		Posts:          2,
+		Replies:        1,
+		LastActivityAt: start + 2000,
+	}, activity[th.BasicUser.Id])
+
+	require.NotNil(t, activity[th.BasicUser2.Id])
+	assert.Equal(t, int64(1), activity[th.BasicUser2.Id].Reactions)
+
+	t.Run("minimum activity", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.AnalyticsSettings.MinimumUserActivity = 2 })
+		defer th.App.UpdateConfig(func(cfg *model.Config) { *cfg.AnalyticsSettings.MinimumUserActivity = 0 })
+
+		activity := getActivity()
+		assert.NotNil(t, activity[th.BasicUser.Id])
+		assert.Nil(t, activity[th.BasicUser2.Id], "users with less activity should be left out")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := th.App.GetUserAnalytics("yesterday", "", 0, 100)
+		assert.NotNil(t, err)
+
+		_, err = th.App.GetUserAnalytics("2000-01-01", "", 0, 100)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestGetSeatReport(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	teamCount := func(report *model.SeatReport, teamId string) int64 {
+		for _, team := range report.Teams {
+			if team.TeamId == teamId {
+				return team.ActiveMemberCount
+			}
+		}
+		t.Fatal("missing team " + teamId)
+		return 0
+	}
+
+	before, err := th.App.GetSeatReport()
+	require.Nil(t, err)
+
+	team2 := th.CreateTeam()
+
+	user := th.CreateUser()
+	th.LinkUserToTeam(user, th.BasicTeam)
+	th.LinkUserToTeam(user, team2)
+
+	bot := store.Must(th.App.Srv.Store.User().Save(&model.User{Email: "success+" + model.NewId() + "@simulator.amazonses.com", Username: "bot" + model.NewId(), IsBot: true})).(*model.User)
+	th.LinkUserToTeam(bot, th.BasicTeam)
+
+	report, err := th.App.GetSeatReport()
+	require.Nil(t, err)
+
+	assert.Equal(t, before.ActiveUserCount+1, report.ActiveUserCount, "users in more than one team should only be counted once")
+	assert.Equal(t, before.BotCount+1, report.BotCount)
+	assert.Equal(t, before.DeactivatedUserCount, report.DeactivatedUserCount)
+	assert.Equal(t, teamCount(before, th.BasicTeam.Id)+2, teamCount(report, th.BasicTeam.Id))
+	assert.Equal(t, int64(1), teamCount(report, team2.Id))
+
+	_, err = th.App.UpdateActive(user, false)
+	require.Nil(t, err)
+
+	report, err = th.App.GetSeatReport()
+	require.Nil(t, err)
+
+	assert.Equal(t, before.ActiveUserCount, report.ActiveUserCount)
+	assert.Equal(t, before.DeactivatedUserCount+1, report.DeactivatedUserCount)
+	assert.Equal(t, teamCount(before, th.BasicTeam.Id)+1, teamCount(report, th.BasicTeam.Id))
+	assert.Equal(t, int64(0), teamCount(report, team2.Id))
+}
+
+func TestReconcileTeamMemberCounts(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	_, err := th.App.ReconcileTeamMemberCounts()
+	require.Nil(t, err)
+
+	corrected, err := th.App.ReconcileTeamMemberCounts()
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), corrected, "shouldn't have anything left to fix")
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const (
+	// Announcements set from other servers or the CLI are picked up at least this often.
+	ANNOUNCEMENT_REFRESH_INTERVAL = time.Minute
+
+	ANNOUNCEMENT_MAX_STORED_LENGTH = 1024
+)
+
+// GetAnnouncement returns the announcement currently shown to clients, or nil if there isn't one.
+func (a *App) GetAnnouncement() *model.Announcement {
+	a.announcementLock.Lock()
+	defer a.announcementLock.Unlock()
+
+	return a.announcement
+}
+
+// SetAnnouncement replaces the system-wide announcement and pushes it to every connected client.
+func (a *App) SetAnnouncement(announcement *model.Announcement) *model.AppError {
+	announcement.SetDefaults()
+	if err := announcement.IsValid(); err != nil {
+		return err
+	}
+
+	if announcement.IsExpired(model.GetMillis()) {
+		return model.NewAppError("SetAnnouncement", "app.announcement.set.expired.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	value := announcement.ToJson()
+	if len(value) > ANNOUNCEMENT_MAX_STORED_LENGTH {
+		return model.NewAppError("SetAnnouncement", "app.announcement.set.too_long.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if result := <-a.Srv.Store.System().SaveOrUpdate(&model.System{Name: model.SYSTEM_ANNOUNCEMENT, Value: value}); result.Err != nil {
+		return result.Err
+	}
+
+	a.updateAnnouncement(announcement, true)
+
+	return nil
+}
+
+// ClearAnnouncement removes the system-wide announcement from every connected client.
+func (a *App) ClearAnnouncement() *model.AppError {
+	if result := <-a.Srv.Store.System().PermanentDeleteByName(model.SYSTEM_ANNOUNCEMENT); result.Err != nil {
+		return result.Err
+	}
+
+	a.updateAnnouncement(nil, true)
+
+	return nil
+}
+
+func (a *App) loadAnnouncement() (*model.Announcement, *model.AppError) {
+	result := <-a.Srv.Store.System().Get()
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	value, ok := result.Data.(model.StringMap)[model.SYSTEM_ANNOUNCEMENT]
+	if !ok {
+		return nil, nil
+	}
+
+	announcement := model.AnnouncementFromJson(strings.NewReader(value))
+	if announcement == nil || announcement.IsExpired(model.GetMillis()) {
+		return nil, nil
+	}
+
+	return announcement, nil
+}
+
+// refreshAnnouncement reloads the announcement from the database, which also drops it once it has expired.
+func (a *App) refreshAnnouncement() {
+	announcement, err := a.loadAnnouncement()
+	if err != nil {
+		mlog.Error("Unable to load the announcement", mlog.String("error", err.Error()))
+		a.scheduleAnnouncementRefresh(a.GetAnnouncement())
+		return
+	}
+
+	a.updateAnnouncement(announcement, false)
+}
+
+// updateAnnouncement makes the announcement the current one and notifies clients if it changed. Clients
+// connected to other servers are notified by their own server unless the change is sent to the cluster.
+func (a *App) updateAnnouncement(announcement *model.Announcement, sendToCluster bool) {
+	a.announcementLock.Lock()
+	changed := announcementJson(a.announcement) != announcementJson(announcement)
+	a.announcement = announcement
+	a.announcementLock.Unlock()
+
+	a.scheduleAnnouncementRefresh(announcement)
+
+	if !changed {
+		return
+	}
+
+	a.regenerateClientConfig()
+
+	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_CONFIG_CHANGED, "", "", "", nil)
+	message.Add("config", a.ClientConfigWithComputed())
+	if sendToCluster {
+		a.Go(func() {
+			a.Publish(message)
+		})
+	} else {
+		a.Go(func() {
+			a.PublishSkipClusterSend(message)
+		})
+	}
+}
+
+func (a *App) scheduleAnnouncementRefresh(announcement *model.Announcement) {
+	a.announcementLock.Lock()
+	defer a.announcementLock.Unlock()
+
+	if a.announcementDone {
+		return
+	}
+
+	if a.announcementTimer != nil {
+		a.announcementTimer.Stop()
+	}
+
+	wait := ANNOUNCEMENT_REFRESH_INTERVAL
+	if announcement != nil && announcement.ExpiresAt > 0 {
+		if untilExpiry := time.Duration(announcement.ExpiresAt-model.GetMillis()) * time.Millisecond; untilExpiry < wait {
+			wait = untilExpiry
+		}
+	}
+
+	a.announcementTimer = time.AfterFunc(wait, a.refreshAnnouncement)
+}
+
+func (a *App) stopAnnouncementRefresh() {
+	a.announcementLock.Lock()
+	defer a.announcementLock.Unlock()
+
+	a.announcementDone = true
+	if a.announcementTimer != nil {
+		a.announcementTimer.Stop()
+		a.announcementTimer = nil
+	}
+}
+
+// applyAnnouncementToClientConfig shows the current announcement in place of the configured banner.
+func (a *App) applyAnnouncementToClientConfig(props map[string]string) {
+	announcement := a.GetAnnouncement()
+	if announcement == nil {
+		return
+	}
+
+	props["EnableBanner"] = "true"
+	props["BannerText"] = announcement.Text
+	props["BannerColor"] = announcement.Color
+	props["BannerTextColor"] = announcement.TextColor
+	props["AllowBannerDismissal"] = strconv.FormatBool(announcement.Dismissable)
+	props["BannerExpiresAt"] = strconv.FormatInt(announcement.ExpiresAt, 10)
+}
+
+func announcementJson(announcement *model.Announcement) string {
+	if announcement == nil {
+		return ""
+	}
+
+	return announcement.ToJson()
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestSetAnnouncement(t *testing.T) {
+	th := Setup()
+	defer th.TearDown()
+
+	defer th.App.ClearAnnouncement()
+
+	require.Nil(t, th.App.SetAnnouncement(&model.Announcement{
+		Text:        "Maintenance tonight",
+		Dismissable: true,
+	}))
+
+	announcement := th.App.GetAnnouncement()
+	require.NotNil(t, announcement)
+	assert.Equal(t, model.ANNOUNCEMENT_SETTINGS_DEFAULT_BANNER_COLOR, announcement.Color)
+
+	config := th.App.ClientConfig()
+	assert.Equal(t, "true", config["EnableBanner"])
+	assert.Equal(t, "Maintenance tonight", config["BannerText"])
+	assert.Equal(t, "true", config["AllowBannerDismissal"])
+
+	loaded, err := th.App.loadAnnouncement()
+	require.Nil(t, err)
+	require.NotNil(t, loaded)
+	assert.Equal(t, "Maintenance tonight", loaded.Text)
+
+	require.Nil(t, th.App.ClearAnnouncement())
+	assert.Nil(t, th.App.GetAnnouncement())
+	assert.NotEqual(t, "Maintenance tonight", th.App.ClientConfig()["BannerText"])
+
+	loaded, err = th.App.loadAnnouncement()
+	require.Nil(t, err)
+	assert.Nil(t, loaded)
+}
+
+func TestAnnouncementExpiry(t *testing.T) {
+	th := Setup()
+	defer th.TearDown()
+
+	defer th.App.ClearAnnouncement()
+
+	err := th.App.SetAnnouncement(&model.Announcement{Text: "Too late", ExpiresAt: model.GetMillis() - 1})
+	require.NotNil(t, err)
+	assert.Equal(t, "app.announcement.set.expired.app_error", err.Id)
+
+	require.Nil(t, th.App.SetAnnouncement(&model.Announcement{Text: "Short lived", ExpiresAt: model.GetMillis() + 500}))
+	assert.Equal(t, "Short lived", th.App.ClientConfig()["BannerText"])
+
+	time.Sleep(time.Second)
+
+	assert.Nil(t, th.App.GetAnnouncement())
+	assert.NotEqual(t, "Short lived", th.App.ClientConfig()["BannerText"])
+}
@@ -13,9 +13,11 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
+	throttled "gopkg.in/throttled/throttled.v2"
 
 	"github.com/mattermost/mattermost-server/einterfaces"
 	ejobs "github.com/mattermost/mattermost-server/einterfaces/jobs"
@@ -29,6 +31,10 @@ import (
 )
 
 const ADVANCED_PERMISSIONS_MIGRATION_KEY = "AdvancedPermissionsMigrationComplete"
+const GUEST_ROLES_MIGRATION_KEY = "GuestRolesMigrationComplete"
+const IMPERSONATION_PERMISSION_MIGRATION_KEY = "ImpersonationPermissionMigrationComplete"
+const SYSTEM_ADMIN_ROLES_MIGRATION_KEY = "SystemAdminRolesMigrationComplete"
+const REMOVE_OTHERS_REACTIONS_MIGRATION_KEY = "RemoveOthersReactionsMigrationComplete"
 
 type App struct {
 	goroutineCount      int32
@@ -45,8 +51,13 @@ type App struct {
 
 	EmailBatching *EmailBatchingJob
 
+	postPipeline          *postPipeline
+	mailQueue             *mailQueue
+	outgoingWebhookQueues outgoingWebhookQueues
+
 	Hubs                        []*Hub
 	HubsStopCheckingForDeadlock chan bool
+	hubRegistry                 *hubRegistry
 
 	Jobs *jobs.JobServer
 
@@ -78,14 +89,17 @@ type App struct {
 
 	newStore func() store.Store
 
-	htmlTemplateWatcher  *utils.HTMLTemplateWatcher
-	sessionCache         *utils.Cache
-	configListenerId     string
-	licenseListenerId    string
-	logListenerId        string
-	disableConfigWatch   bool
-	configWatcher        *utils.ConfigWatcher
-	asymmetricSigningKey *ecdsa.PrivateKey
+	htmlTemplateWatcher   *utils.HTMLTemplateWatcher
+	sessionCache          *utils.Cache
+	configListenerId      string
+	licenseListenerId     string
+	logListenerId         string
+	secretScanListenerId  string
+	rateLimiterListenerId string
+	disableConfigWatch    bool
+	configWatcher         *utils.ConfigWatcher
+	configSaveLock        sync.Mutex
+	asymmetricSigningKey  *ecdsa.PrivateKey
 
 	pluginCommands     []*PluginCommand
 	pluginCommandsLock sync.RWMutex
@@ -93,6 +107,70 @@ type App struct {
 	clientConfig     map[string]string
 	clientConfigHash string
 	diagnosticId     string
+
+	announcement      *model.Announcement
+	announcementLock  sync.Mutex
+	announcementTimer *time.Timer
+	announcementDone  bool
+
+	maintenanceMode     *model.MaintenanceMode
+	maintenanceModeLock sync.RWMutex
+	maintenanceModeTask *model.ScheduledTask
+
+	drainStatus *model.DrainStatus
+	drainLock   sync.Mutex
+	drainStop   chan struct{}
+
+	notificationTraces     map[string]int64
+	notificationTracesLock sync.RWMutex
+	notificationTraceCache *utils.Cache
+
+	recentRequestTraces     []*model.RequestTrace
+	recentRequestTracesLock sync.Mutex
+
+	generatingSupportPacket int32
+
+	userTypingCache *utils.Cache
+
+	loginFailures     *utils.Cache
+	loginFailuresLock sync.Mutex
+	captchaVerifier   CaptchaVerifier
+
+	postIdempotencyCache *utils.Cache
+
+	featureFlagOverrides   map[string]string
+	configuredFeatureFlags map[string]string
+	featureFlagsLock       sync.RWMutex
+
+	linkBlocklist     *model.LinkBlocklist
+	linkBlocklistLock sync.RWMutex
+
+	lastFourByteCharactersWarning int64
+	fourByteCharactersWarningLock sync.Mutex
+
+	systemBotLock sync.Mutex
+
+	// sharedFilesLock is held while a duplicate upload is saved to share a stored file and while deleted files are
+	// checked for other references before they're removed, so that a shared file isn't removed from under an upload.
+	sharedFilesLock sync.Mutex
+
+	sessionActivity     map[string]int64
+	userActivity        map[string]int64
+	sessionActivityLock sync.Mutex
+	sessionActivityTask *model.ScheduledTask
+
+	pendingChannelViews  map[string]map[string]*model.ChannelViewedAt
+	pendingStatuses      map[string]*pendingStatus
+	writeBehindLock      sync.Mutex
+	writeBehindFlushLock sync.Mutex
+	writeBehindTask      *model.ScheduledTask
+
+	webSocketOutbox *webSocketOutbox
+
+	shortPermalinkLimiter     *throttled.GCRARateLimiter
+	shortPermalinkCleanupTask *model.ScheduledTask
+
+	secretScanningRules atomic.Value
 }
 
 var appCount = 0
@@ -108,13 +186,18 @@ func New(options ...Option) (outApp *App, outErr error) {
 	app := &App{
 		goroutineExitSignal: make(chan struct{}, 1),
 		Srv: &Server{
-			Router: mux.NewRouter(),
+			Router:      mux.NewRouter(),
+			LocalRouter: mux.NewRouter(),
 		},
-		sessionCache:     utils.NewLru(model.SESSION_CACHE_SIZE),
-		configFile:       "config.json",
-		configListeners:  make(map[string]func(*model.Config, *model.Config)),
-		clientConfig:     make(map[string]string),
-		licenseListeners: map[string]func(){},
+		sessionCache:           utils.NewLru(model.SESSION_CACHE_SIZE),
+		configFile:             "config.json",
+		configListeners:        make(map[string]func(*model.Config, *model.Config)),
+		clientConfig:           make(map[string]string),
+		licenseListeners:       map[string]func(){},
+		notificationTraces:     make(map[string]int64),
+		notificationTraceCache: utils.NewLru(NOTIFICATION_TRACE_CACHE_SIZE),
+		userTypingCache:        utils.NewLru(USER_TYPING_CACHE_SIZE),
+		loginFailures:          utils.NewLru(LOGIN_FAILURE_CACHE_SIZE),
 	}
 	defer func() {
 		if outErr != nil {
@@ -178,6 +261,7 @@ func New(options ...Option) (outApp *App, outErr error) {
 		})
 
 	})
+	app.initSecretScanning()
 	app.regenerateClientConfig()
 
 	mlog.Info("Server is initializing...")
@@ -185,8 +269,19 @@ func New(options ...Option) (outApp *App, outErr error) {
 	app.initEnterprise()
 
 	if app.newStore == nil {
+		skipMigrations := *app.Config().ServiceSettings.SkipMigrations
+		if skipMigrations {
+			if err := sqlstore.CheckSchemaIsCurrent(app.Config().SqlSettings); err != nil {
+				return nil, errors.Wrap(err, "unable to start without migrating the database, run the db migrate command first")
+			}
+		}
+
 		app.newStore = func() store.Store {
-			return store.NewLayeredStore(sqlstore.NewSqlSupplier(app.Config().SqlSettings, app.Metrics), app.Metrics, app.Cluster)
+			cacheProvider := utils.NewCacheProvider(app.Config().CacheSettings, app.Metrics, app.Cluster)
+			if skipMigrations {
+				return store.NewLayeredStore(sqlstore.NewSqlSupplierWithoutMigrations(app.Config().SqlSettings, app.Metrics, cacheProvider), app.Metrics, app.Cluster)
+			}
+			return store.NewLayeredStore(sqlstore.NewSqlSupplier(app.Config().SqlSettings, app.Metrics, cacheProvider), app.Metrics, app.Cluster)
 		}
 	}
 
@@ -201,6 +296,18 @@ func New(options ...Option) (outApp *App, outErr error) {
 		return nil, errors.Wrapf(err, "unable to ensure asymmetric signing key")
 	}
 
+	app.loadFeatureFlagOverrides()
+	app.refreshAnnouncement()
+	app.startMaintenanceModeRefresh()
+	app.startSessionActivityFlush()
+	app.startWriteBehindFlush()
+	app.startPostPipeline()
+	app.startMailQueue()
+	if err := app.startShortPermalinks(); err != nil {
+		return nil, errors.Wrapf(err, "unable to start short permalinks")
+	}
+	app.postIdempotencyCache = utils.NewLru(*app.Config().ServiceSettings.PostIdempotencyCacheSize)
+
 	app.initJobs()
 
 	app.initBuiltInPlugins()
@@ -227,12 +334,20 @@ func (a *App) Shutdown() {
 	mlog.Info("Stopping Server...")
 
 	a.StopServer()
+	a.stopPostPipeline()
+	a.stopMailQueue()
+	a.stopAnnouncementRefresh()
+	a.stopMaintenanceModeRefresh()
+	a.stopDrain()
 	a.HubStop()
 
 	a.ShutDownPlugins()
 	a.WaitForGoroutines()
 
 	if a.Srv.Store != nil {
+		a.stopSessionActivityFlush()
+		a.stopWriteBehindFlush()
+		a.stopShortPermalinks()
 		a.Srv.Store.Close()
 	}
 	a.Srv = nil
@@ -244,6 +359,7 @@ func (a *App) Shutdown() {
 	a.RemoveConfigListener(a.configListenerId)
 	a.RemoveLicenseListener(a.licenseListenerId)
 	a.RemoveConfigListener(a.logListenerId)
+	a.RemoveConfigListener(a.secretScanListenerId)
 	mlog.Info("Server stopped")
 
 	a.DisableConfigWatch()
@@ -321,6 +437,72 @@ func RegisterJobsLdapSyncInterface(f func(*App) ejobs.LdapSyncInterface) {
 	jobsLdapSyncInterface = f
 }
 
+var jobsBasicRetentionJobInterface func(*App) ejobs.BasicRetentionJobInterface
+
+func RegisterJobsBasicRetentionJobInterface(f func(*App) ejobs.BasicRetentionJobInterface) {
+	jobsBasicRetentionJobInterface = f
+}
+
+var jobsUserDeactivationJobInterface func(*App) ejobs.UserDeactivationJobInterface
+
+func RegisterJobsUserDeactivationJobInterface(f func(*App) ejobs.UserDeactivationJobInterface) {
+	jobsUserDeactivationJobInterface = f
+}
+
+var jobsPreferenceCleanupJobInterface func(*App) ejobs.PreferenceCleanupJobInterface
+
+func RegisterJobsPreferenceCleanupJobInterface(f func(*App) ejobs.PreferenceCleanupJobInterface) {
+	jobsPreferenceCleanupJobInterface = f
+}
+
+var jobsAnalyticsRollupJobInterface func(*App) ejobs.AnalyticsRollupJobInterface
+
+func RegisterJobsAnalyticsRollupJobInterface(f func(*App) ejobs.AnalyticsRollupJobInterface) {
+	jobsAnalyticsRollupJobInterface = f
+}
+
+var jobsIdleUserDeactivationJobInterface func(*App) ejobs.IdleUserDeactivationJobInterface
+
+func RegisterJobsIdleUserDeactivationJobInterface(f func(*App) ejobs.IdleUserDeactivationJobInterface) {
+	jobsIdleUserDeactivationJobInterface = f
+}
+
+var jobsChannelMemberCountsJobInterface func(*App) ejobs.ChannelMemberCountsJobInterface
+
+func RegisterJobsChannelMemberCountsJobInterface(f func(*App) ejobs.ChannelMemberCountsJobInterface) {
+	jobsChannelMemberCountsJobInterface = f
+}
+
+var jobsChannelArchivingJobInterface func(*App) ejobs.ChannelArchivingJobInterface
+
+func RegisterJobsChannelArchivingJobInterface(f func(*App) ejobs.ChannelArchivingJobInterface) {
+	jobsChannelArchivingJobInterface = f
+}
+
+var jobsFileStorageUsageJobInterface func(*App) ejobs.FileStorageUsageJobInterface
+
+func RegisterJobsFileStorageUsageJobInterface(f func(*App) ejobs.FileStorageUsageJobInterface) {
+	jobsFileStorageUsageJobInterface = f
+}
+
+var jobsChannelDeletionJobInterface func(*App) ejobs.ChannelDeletionJobInterface
+
+func RegisterJobsChannelDeletionJobInterface(f func(*App) ejobs.ChannelDeletionJobInterface) {
+	jobsChannelDeletionJobInterface = f
+}
+
+var jobsFileStoreVerificationJobInterface func(*App) ejobs.FileStoreVerificationJobInterface
+
+func RegisterJobsFileStoreVerificationJobInterface(f func(*App) ejobs.FileStoreVerificationJobInterface) {
+	jobsFileStoreVerificationJobInterface = f
+}
+
+var jobsTeamDeletionJobInterface func(*App) ejobs.TeamDeletionJobInterface
+
+func RegisterJobsTeamDeletionJobInterface(f func(*App) ejobs.TeamDeletionJobInterface) {
+	jobsTeamDeletionJobInterface = f
+}
+
 var ldapInterface func(*App) einterfaces.LdapInterface
 
 func RegisterLdapInterface(f func(*App) einterfaces.LdapInterface) {
@@ -415,6 +597,39 @@ func (a *App) initJobs() {
 	if jobsLdapSyncInterface != nil {
 		a.Jobs.LdapSync = jobsLdapSyncInterface(a)
 	}
+	if jobsBasicRetentionJobInterface != nil {
+		a.Jobs.BasicRetention = jobsBasicRetentionJobInterface(a)
+	}
+	if jobsUserDeactivationJobInterface != nil {
+		a.Jobs.UserDeactivation = jobsUserDeactivationJobInterface(a)
+	}
+	if jobsPreferenceCleanupJobInterface != nil {
+		a.Jobs.PreferenceCleanup = jobsPreferenceCleanupJobInterface(a)
+	}
+	if jobsAnalyticsRollupJobInterface != nil {
+		a.Jobs.AnalyticsRollup = jobsAnalyticsRollupJobInterface(a)
+	}
+	if jobsIdleUserDeactivationJobInterface != nil {
+		a.Jobs.IdleUserDeactivation = jobsIdleUserDeactivationJobInterface(a)
+	}
+	if jobsChannelMemberCountsJobInterface != nil {
+		a.Jobs.ChannelMemberCounts = jobsChannelMemberCountsJobInterface(a)
+	}
+	if jobsChannelArchivingJobInterface != nil {
+		a.Jobs.ChannelArchiving = jobsChannelArchivingJobInterface(a)
+	}
+	if jobsFileStorageUsageJobInterface != nil {
+		a.Jobs.FileStorageUsage = jobsFileStorageUsageJobInterface(a)
+	}
+	if jobsChannelDeletionJobInterface != nil {
+		a.Jobs.ChannelDeletion = jobsChannelDeletionJobInterface(a)
+	}
+	if jobsFileStoreVerificationJobInterface != nil {
+		a.Jobs.FileStoreVerification = jobsFileStoreVerificationJobInterface(a)
+	}
+	if jobsTeamDeletionJobInterface != nil {
+		a.Jobs.TeamDeletion = jobsTeamDeletionJobInterface(a)
+	}
 }
 
 func (a *App) DiagnosticId() string {
@@ -511,18 +726,58 @@ func (a *App) HTTPClient(trustURLs bool) *http.Client {
 	return utils.NewHTTPClient(insecure, allowHost, allowIP)
 }
 
+// GetIpAddress returns the address of the client that made the request, only trusting the address passed along by
+// a reverse proxy when the request comes from one that's configured as trusted.
+func (a *App) GetIpAddress(r *http.Request) string {
+	settings := a.Config().ServiceSettings
+	return utils.GetIpAddress(r, *settings.TrustedProxyIPHeader, *settings.TrustedProxyCIDRs)
+}
+
 func (a *App) Handle404(w http.ResponseWriter, r *http.Request) {
 	err := model.NewAppError("Handle404", "api.context.404.app_error", nil, "", http.StatusNotFound)
 
-	mlog.Debug(fmt.Sprintf("%v: code=404 ip=%v", r.URL.Path, utils.GetIpAddress(r)))
+	mlog.Debug(fmt.Sprintf("%v: code=404 ip=%v", r.URL.Path, a.GetIpAddress(r)))
 
 	utils.RenderWebAppError(w, r, err, a.AsymmetricSigningKey())
 }
 
+// advancedMigrationKeys are the names of the System values that DoAdvancedPermissionsMigration marks each of its
+// migrations as completed with.
+var advancedMigrationKeys = []string{
+	ADVANCED_PERMISSIONS_MIGRATION_KEY,
+	GUEST_ROLES_MIGRATION_KEY,
+	IMPERSONATION_PERMISSION_MIGRATION_KEY,
+	SYSTEM_ADMIN_ROLES_MIGRATION_KEY,
+	REMOVE_OTHERS_REACTIONS_MIGRATION_KEY,
+}
+
+// GetPendingAdvancedMigrations returns the keys of the migrations done by DoAdvancedPermissionsMigration that haven't
+// been completed.
+func (a *App) GetPendingAdvancedMigrations() ([]string, *model.AppError) {
+	result := <-a.Srv.Store.System().Get()
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	props := result.Data.(model.StringMap)
+
+	pending := []string{}
+	for _, key := range advancedMigrationKeys {
+		if _, ok := props[key]; !ok {
+			pending = append(pending, key)
+		}
+	}
+
+	return pending, nil
+}
+
 // This function migrates the default built in roles from code/config to the database.
 func (a *App) DoAdvancedPermissionsMigration() {
 	// If the migration is already marked as completed, don't do it again.
 	if result := <-a.Srv.Store.System().GetByName(ADVANCED_PERMISSIONS_MIGRATION_KEY); result.Err == nil {
+		a.doGuestRolesMigration()
+		a.doImpersonationPermissionMigration()
+		a.doSystemAdminRolesMigration()
+		a.doRemoveOthersReactionsMigration()
 		return
 	}
 
@@ -578,5 +833,184 @@ func (a *App) DoAdvancedPermissionsMigration() {
 	if result := <-a.Srv.Store.System().Save(&system); result.Err != nil {
 		mlog.Critical("Failed to mark advanced permissions migration as completed.")
 		mlog.Critical(fmt.Sprint(result.Err))
+		return
+	}
+
+	a.doGuestRolesMigration()
+	a.doImpersonationPermissionMigration()
+	a.doSystemAdminRolesMigration()
+	a.doRemoveOthersReactionsMigration()
+}
+
+// doGuestRolesMigration adds the guest roles, and the permissions to manage guests, to servers whose
+// roles were migrated to the database before guest accounts existed.
+func (a *App) doGuestRolesMigration() {
+	if result := <-a.Srv.Store.System().GetByName(GUEST_ROLES_MIGRATION_KEY); result.Err == nil {
+		return
+	}
+
+	defaultRoles := model.MakeDefaultRoles()
+	allSucceeded := true
+
+	for _, roleName := range []string{model.SYSTEM_GUEST_ROLE_ID, model.TEAM_GUEST_ROLE_ID, model.CHANNEL_GUEST_ROLE_ID} {
+		if result := <-a.Srv.Store.Role().GetByName(roleName); result.Err == nil {
+			continue
+		}
+
+		if result := <-a.Srv.Store.Role().Save(defaultRoles[roleName]); result.Err != nil {
+			mlog.Critical("Failed to migrate guest role to database.")
+			mlog.Critical(fmt.Sprint(result.Err))
+			allSucceeded = false
+		}
+	}
+
+	guestPermissions := map[string][]string{
+		model.TEAM_ADMIN_ROLE_ID:   {model.PERMISSION_INVITE_GUEST.Id},
+		model.SYSTEM_ADMIN_ROLE_ID: {model.PERMISSION_INVITE_GUEST.Id, model.PERMISSION_PROMOTE_GUEST.Id, model.PERMISSION_DEMOTE_TO_GUEST.Id},
+	}
+
+	if !a.addPermissionsToRoles(guestPermissions) {
+		allSucceeded = false
+	}
+
+	if !allSucceeded {
+		return
+	}
+
+	system := model.System{
+		Name:  GUEST_ROLES_MIGRATION_KEY,
+		Value: "true",
+	}
+
+	if result := <-a.Srv.Store.System().Save(&system); result.Err != nil {
+		mlog.Critical("Failed to mark guest roles migration as completed.")
+		mlog.Critical(fmt.Sprint(result.Err))
+	}
+}
+
+// doImpersonationPermissionMigration lets system admins impersonate users on servers whose roles were
+// migrated to the database before impersonation existed.
+func (a *App) doImpersonationPermissionMigration() {
+	if result := <-a.Srv.Store.System().GetByName(IMPERSONATION_PERMISSION_MIGRATION_KEY); result.Err == nil {
+		return
+	}
+
+	if !a.addPermissionsToRoles(map[string][]string{model.SYSTEM_ADMIN_ROLE_ID: {model.PERMISSION_IMPERSONATE_USER.Id}}) {
+		return
+	}
+
+	system := model.System{
+		Name:  IMPERSONATION_PERMISSION_MIGRATION_KEY,
+		Value: "true",
+	}
+
+	if result := <-a.Srv.Store.System().Save(&system); result.Err != nil {
+		mlog.Critical("Failed to mark impersonation permission migration as completed.")
+		mlog.Critical(fmt.Sprint(result.Err))
+	}
+}
+
+// doSystemAdminRolesMigration adds the read only admin and user manager roles, and the permissions that
+// they're made up of, to servers whose roles were migrated to the database before those roles existed.
+func (a *App) doSystemAdminRolesMigration() {
+	if result := <-a.Srv.Store.System().GetByName(SYSTEM_ADMIN_ROLES_MIGRATION_KEY); result.Err == nil {
+		return
+	}
+
+	defaultRoles := model.MakeDefaultRoles()
+	allSucceeded := true
+
+	for _, roleName := range []string{model.SYSTEM_READ_ONLY_ADMIN_ROLE_ID, model.SYSTEM_USER_MANAGER_ROLE_ID} {
+		if result := <-a.Srv.Store.Role().GetByName(roleName); result.Err == nil {
+			continue
+		}
+
+		if result := <-a.Srv.Store.Role().Save(defaultRoles[roleName]); result.Err != nil {
+			mlog.Critical("Failed to migrate system admin role to database.", mlog.String("role", roleName))
+			mlog.Critical(fmt.Sprint(result.Err))
+			allSucceeded = false
+		}
+	}
+
+	if !a.addPermissionsToRoles(map[string][]string{
+		model.SYSTEM_ADMIN_ROLE_ID: {model.PERMISSION_READ_SYSTEM_CONSOLE.Id, model.PERMISSION_MANAGE_USERS.Id},
+	}) {
+		allSucceeded = false
+	}
+
+	if !allSucceeded {
+		return
+	}
+
+	system := model.System{
+		Name:  SYSTEM_ADMIN_ROLES_MIGRATION_KEY,
+		Value: "true",
+	}
+
+	if result := <-a.Srv.Store.System().Save(&system); result.Err != nil {
+		mlog.Critical("Failed to mark system admin roles migration as completed.")
+		mlog.Critical(fmt.Sprint(result.Err))
 	}
 }
+
+// doRemoveOthersReactionsMigration lets channel and team admins remove other users' reactions in the channels that
+// they manage, which used to need the permission to be granted across the whole system.
+func (a *App) doRemoveOthersReactionsMigration() {
+	if result := <-a.Srv.Store.System().GetByName(REMOVE_OTHERS_REACTIONS_MIGRATION_KEY); result.Err == nil {
+		return
+	}
+
+	if !a.addPermissionsToRoles(map[string][]string{
+		model.CHANNEL_ADMIN_ROLE_ID: {model.PERMISSION_REMOVE_OTHERS_REACTIONS.Id},
+		model.TEAM_ADMIN_ROLE_ID:    {model.PERMISSION_REMOVE_OTHERS_REACTIONS.Id},
+	}) {
+		return
+	}
+
+	system := model.System{
+		Name:  REMOVE_OTHERS_REACTIONS_MIGRATION_KEY,
+		Value: "true",
+	}
+
+	if result := <-a.Srv.Store.System().Save(&system); result.Err != nil {
+		mlog.Critical("Failed to mark remove others reactions migration as completed.")
+		mlog.Critical(fmt.Sprint(result.Err))
+	}
+}
+
+// addPermissionsToRoles adds permissions to the roles with the given names, returning false if any of
+// the roles couldn't be updated.
+func (a *App) addPermissionsToRoles(permissionsByRole map[string][]string) bool {
+	allSucceeded := true
+
+	for roleName, permissions := range permissionsByRole {
+		result := <-a.Srv.Store.Role().GetByName(roleName)
+		if result.Err != nil {
+			mlog.Critical("Failed to add permissions to role.", mlog.String("role", roleName))
+			mlog.Critical(fmt.Sprint(result.Err))
+			allSucceeded = false
+			continue
+		}
+
+		role := result.Data.(*model.Role)
+		changed := false
+		for _, permission := range permissions {
+			if !utils.StringInSlice(permission, role.Permissions) {
+				role.Permissions = append(role.Permissions, permission)
+				changed = true
+			}
+		}
+
+		if !changed {
+			continue
+		}
+
+		if result := <-a.Srv.Store.Role().Save(role); result.Err != nil {
+			mlog.Critical("Failed to add permissions to role.", mlog.String("role", roleName))
+			mlog.Critical(fmt.Sprint(result.Err))
+			allSucceeded = false
+		}
+	}
+
+	return allSucceeded
+}
@@ -107,6 +107,11 @@ func TestDoAdvancedPermissionsMigration(t *testing.T) {
 		"system_user_access_token",
 		"team_post_all",
 		"team_post_all_public",
+		"system_guest",
+		"team_guest",
+		"channel_guest",
+		"system_read_only_admin",
+		"system_user_manager",
 	}
 
 	roles1, err1 := th.App.GetRolesByNames(roleNames)
@@ -129,6 +134,7 @@ func TestDoAdvancedPermissionsMigration(t *testing.T) {
 		},
 		"channel_admin": []string{
 			model.PERMISSION_MANAGE_CHANNEL_ROLES.Id,
+			model.PERMISSION_REMOVE_OTHERS_REACTIONS.Id,
 		},
 		"team_user": []string{
 			model.PERMISSION_LIST_TEAM_CHANNELS.Id,
@@ -161,6 +167,8 @@ func TestDoAdvancedPermissionsMigration(t *testing.T) {
 			model.PERMISSION_MANAGE_SLASH_COMMANDS.Id,
 			model.PERMISSION_MANAGE_OTHERS_SLASH_COMMANDS.Id,
 			model.PERMISSION_MANAGE_WEBHOOKS.Id,
+			model.PERMISSION_INVITE_GUEST.Id,
+			model.PERMISSION_REMOVE_OTHERS_REACTIONS.Id,
 			model.PERMISSION_DELETE_POST.Id,
 			model.PERMISSION_DELETE_OTHERS_POSTS.Id,
 		},
@@ -168,6 +176,7 @@ func TestDoAdvancedPermissionsMigration(t *testing.T) {
 			model.PERMISSION_CREATE_DIRECT_CHANNEL.Id,
 			model.PERMISSION_CREATE_GROUP_CHANNEL.Id,
 			model.PERMISSION_PERMANENT_DELETE_USER.Id,
+			model.PERMISSION_MANAGE_CUSTOM_GROUPS.Id,
 			model.PERMISSION_CREATE_TEAM.Id,
 		},
 		"system_post_all": []string{
@@ -209,7 +218,12 @@ func TestDoAdvancedPermissionsMigration(t *testing.T) {
 			model.PERMISSION_CREATE_USER_ACCESS_TOKEN.Id,
 			model.PERMISSION_READ_USER_ACCESS_TOKEN.Id,
 			model.PERMISSION_REVOKE_USER_ACCESS_TOKEN.Id,
-			model.PERMISSION_REMOVE_OTHERS_REACTIONS.Id,
+			model.PERMISSION_MANAGE_CUSTOM_GROUPS.Id,
+			model.PERMISSION_PROMOTE_GUEST.Id,
+			model.PERMISSION_DEMOTE_TO_GUEST.Id,
+			model.PERMISSION_IMPERSONATE_USER.Id,
+			model.PERMISSION_READ_SYSTEM_CONSOLE.Id,
+			model.PERMISSION_MANAGE_USERS.Id,
 			model.PERMISSION_LIST_TEAM_CHANNELS.Id,
 			model.PERMISSION_JOIN_PUBLIC_CHANNELS.Id,
 			model.PERMISSION_READ_PUBLIC_CHANNEL.Id,
@@ -230,8 +244,36 @@ func TestDoAdvancedPermissionsMigration(t *testing.T) {
 			model.PERMISSION_MANAGE_SLASH_COMMANDS.Id,
 			model.PERMISSION_MANAGE_OTHERS_SLASH_COMMANDS.Id,
 			model.PERMISSION_MANAGE_WEBHOOKS.Id,
+			model.PERMISSION_INVITE_GUEST.Id,
+			model.PERMISSION_REMOVE_OTHERS_REACTIONS.Id,
 			model.PERMISSION_EDIT_POST.Id,
 		},
+		"channel_guest": []string{
+			model.PERMISSION_READ_CHANNEL.Id,
+			model.PERMISSION_ADD_REACTION.Id,
+			model.PERMISSION_REMOVE_REACTION.Id,
+			model.PERMISSION_UPLOAD_FILE.Id,
+			model.PERMISSION_CREATE_POST.Id,
+			model.PERMISSION_USE_SLASH_COMMANDS.Id,
+		},
+		"team_guest": []string{
+			model.PERMISSION_VIEW_TEAM.Id,
+		},
+		"system_guest": []string{
+			model.PERMISSION_CREATE_DIRECT_CHANNEL.Id,
+			model.PERMISSION_CREATE_GROUP_CHANNEL.Id,
+		},
+		"system_read_only_admin": []string{
+			model.PERMISSION_READ_SYSTEM_CONSOLE.Id,
+			model.PERMISSION_LIST_USERS_WITHOUT_TEAM.Id,
+		},
+		"system_user_manager": []string{
+			model.PERMISSION_MANAGE_USERS.Id,
+			model.PERMISSION_LIST_USERS_WITHOUT_TEAM.Id,
+			model.PERMISSION_VIEW_TEAM.Id,
+			model.PERMISSION_ADD_USER_TO_TEAM.Id,
+			model.PERMISSION_REMOVE_USER_FROM_TEAM.Id,
+		},
 	}
 
 	// Check the migration matches what's expected.
@@ -294,6 +336,7 @@ func TestDoAdvancedPermissionsMigration(t *testing.T) {
 		},
 		"channel_admin": []string{
 			model.PERMISSION_MANAGE_CHANNEL_ROLES.Id,
+			model.PERMISSION_REMOVE_OTHERS_REACTIONS.Id,
 		},
 		"team_user": []string{
 			model.PERMISSION_LIST_TEAM_CHANNELS.Id,
@@ -324,6 +367,8 @@ func TestDoAdvancedPermissionsMigration(t *testing.T) {
 			model.PERMISSION_MANAGE_SLASH_COMMANDS.Id,
 			model.PERMISSION_MANAGE_OTHERS_SLASH_COMMANDS.Id,
 			model.PERMISSION_MANAGE_WEBHOOKS.Id,
+			model.PERMISSION_INVITE_GUEST.Id,
+			model.PERMISSION_REMOVE_OTHERS_REACTIONS.Id,
 			model.PERMISSION_MANAGE_PUBLIC_CHANNEL_PROPERTIES.Id,
 			model.PERMISSION_MANAGE_PRIVATE_CHANNEL_PROPERTIES.Id,
 			model.PERMISSION_DELETE_POST.Id,
@@ -333,6 +378,7 @@ func TestDoAdvancedPermissionsMigration(t *testing.T) {
 			model.PERMISSION_CREATE_DIRECT_CHANNEL.Id,
 			model.PERMISSION_CREATE_GROUP_CHANNEL.Id,
 			model.PERMISSION_PERMANENT_DELETE_USER.Id,
+			model.PERMISSION_MANAGE_CUSTOM_GROUPS.Id,
 			model.PERMISSION_CREATE_TEAM.Id,
 		},
 		"system_post_all": []string{
@@ -374,7 +420,12 @@ func TestDoAdvancedPermissionsMigration(t *testing.T) {
 			model.PERMISSION_CREATE_USER_ACCESS_TOKEN.Id,
 			model.PERMISSION_READ_USER_ACCESS_TOKEN.Id,
 			model.PERMISSION_REVOKE_USER_ACCESS_TOKEN.Id,
-			model.PERMISSION_REMOVE_OTHERS_REACTIONS.Id,
+			model.PERMISSION_MANAGE_CUSTOM_GROUPS.Id,
+			model.PERMISSION_PROMOTE_GUEST.Id,
+			model.PERMISSION_DEMOTE_TO_GUEST.Id,
+			model.PERMISSION_IMPERSONATE_USER.Id,
+			model.PERMISSION_READ_SYSTEM_CONSOLE.Id,
+			model.PERMISSION_MANAGE_USERS.Id,
 			model.PERMISSION_LIST_TEAM_CHANNELS.Id,
 			model.PERMISSION_JOIN_PUBLIC_CHANNELS.Id,
 			model.PERMISSION_READ_PUBLIC_CHANNEL.Id,
@@ -395,8 +446,36 @@ func TestDoAdvancedPermissionsMigration(t *testing.T) {
 			model.PERMISSION_MANAGE_SLASH_COMMANDS.Id,
 			model.PERMISSION_MANAGE_OTHERS_SLASH_COMMANDS.Id,
 			model.PERMISSION_MANAGE_WEBHOOKS.Id,
+			model.PERMISSION_INVITE_GUEST.Id,
+			model.PERMISSION_REMOVE_OTHERS_REACTIONS.Id,
 			model.PERMISSION_EDIT_POST.Id,
 		},
+		"channel_guest": []string{
+			model.PERMISSION_READ_CHANNEL.Id,
+			model.PERMISSION_ADD_REACTION.Id,
+			model.PERMISSION_REMOVE_REACTION.Id,
+			model.PERMISSION_UPLOAD_FILE.Id,
+			model.PERMISSION_CREATE_POST.Id,
+			model.PERMISSION_USE_SLASH_COMMANDS.Id,
+		},
+		"team_guest": []string{
+			model.PERMISSION_VIEW_TEAM.Id,
+		},
+		"system_guest": []string{
+			model.PERMISSION_CREATE_DIRECT_CHANNEL.Id,
+			model.PERMISSION_CREATE_GROUP_CHANNEL.Id,
+		},
+		"system_read_only_admin": []string{
+			model.PERMISSION_READ_SYSTEM_CONSOLE.Id,
+			model.PERMISSION_LIST_USERS_WITHOUT_TEAM.Id,
+		},
+		"system_user_manager": []string{
+			model.PERMISSION_MANAGE_USERS.Id,
+			model.PERMISSION_LIST_USERS_WITHOUT_TEAM.Id,
+			model.PERMISSION_VIEW_TEAM.Id,
+			model.PERMISSION_ADD_USER_TO_TEAM.Id,
+			model.PERMISSION_REMOVE_USER_FROM_TEAM.Id,
+		},
 	}
 
 	roles3, err3 := th.App.GetRolesByNames(roleNames)
@@ -53,7 +53,7 @@ var testClusterInterface *FakeClusterInterface
 func UseTestStore(container *storetest.RunningContainer, settings *model.SqlSettings) {
 	testClusterInterface = &FakeClusterInterface{}
 	testStoreContainer = container
-	testStoreSqlSupplier = sqlstore.NewSqlSupplier(*settings, nil)
+	testStoreSqlSupplier = sqlstore.NewSqlSupplier(*settings, nil, nil)
 	testStore = &persistentTestStore{store.NewLayeredStore(testStoreSqlSupplier, nil, testClusterInterface)}
 }
 
@@ -397,6 +397,22 @@ func (me *TestHelper) ResetRoleMigration() {
 	if _, err := testStoreSqlSupplier.GetMaster().Exec("DELETE from Systems where Name = :Name", map[string]interface{}{"Name": ADVANCED_PERMISSIONS_MIGRATION_KEY}); err != nil {
 		panic(err)
 	}
+
+	if _, err := testStoreSqlSupplier.GetMaster().Exec("DELETE from Systems where Name = :Name", map[string]interface{}{"Name": GUEST_ROLES_MIGRATION_KEY}); err != nil {
+		panic(err)
+	}
+
+	if _, err := testStoreSqlSupplier.GetMaster().Exec("DELETE from Systems where Name = :Name", map[string]interface{}{"Name": IMPERSONATION_PERMISSION_MIGRATION_KEY}); err != nil {
+		panic(err)
+	}
+
+	if _, err := testStoreSqlSupplier.GetMaster().Exec("DELETE from Systems where Name = :Name", map[string]interface{}{"Name": SYSTEM_ADMIN_ROLES_MIGRATION_KEY}); err != nil {
+		panic(err)
+	}
+
+	if _, err := testStoreSqlSupplier.GetMaster().Exec("DELETE from Systems where Name = :Name", map[string]interface{}{"Name": REMOVE_OTHERS_REACTIONS_MIGRATION_KEY}); err != nil {
+		panic(err)
+	}
 }
 
 type FakeClusterInterface struct {
@@ -37,6 +37,10 @@ func (a *App) SessionHasPermissionToChannel(session model.Session, channelId str
 		return false
 	}
 
+	if a.sessionIsModeratedInChannel(session, channelId, model.ChannelModeratedPermissionFor(permission.Id)) {
+		return false
+	}
+
 	cmc := a.Srv.Store.Channel().GetAllChannelMembersForUser(session.UserId, true)
 
 	var channelRoles []string
@@ -61,6 +65,14 @@ func (a *App) SessionHasPermissionToChannel(session model.Session, channelId str
 }
 
 func (a *App) SessionHasPermissionToChannelByPost(session model.Session, postId string, permission *model.Permission) bool {
+	if name := model.ChannelModeratedPermissionFor(permission.Id); name != "" {
+		if result := <-a.Srv.Store.Channel().GetForPost(postId); result.Err == nil {
+			if a.sessionIsModeratedInChannel(session, result.Data.(*model.Channel).Id, name) {
+				return false
+			}
+		}
+	}
+
 	var channelMember *model.ChannelMember
 	if result := <-a.Srv.Store.Channel().GetMemberForPost(postId, session.UserId); result.Err == nil {
 		channelMember = result.Data.(*model.ChannelMember)
@@ -88,9 +100,45 @@ func (a *App) SessionHasPermissionToUser(session model.Session, userId string) b
 	}
 
 	if a.SessionHasPermissionTo(session, model.PERMISSION_EDIT_OTHER_USERS) {
+		// Only system admins can edit other admins so that nobody can gain an admin role by taking over the
+		// account of someone who has it
+		return a.SessionHasPermissionTo(session, model.PERMISSION_MANAGE_SYSTEM) || !a.isPrivilegedSystemUser(userId)
+	}
+
+	return false
+}
+
+// SessionHasPermissionToManageUser returns true if the session can edit, activate, deactivate and set the password
+// of the given user, which user managers can do for anyone who doesn't have a privileged system role.
+func (a *App) SessionHasPermissionToManageUser(session model.Session, userId string) bool {
+	if a.SessionHasPermissionTo(session, model.PERMISSION_MANAGE_SYSTEM) {
 		return true
 	}
 
+	return a.SessionHasPermissionTo(session, model.PERMISSION_MANAGE_USERS) && !a.isPrivilegedSystemUser(userId)
+}
+
+// privilegedSystemRoles are the system roles that grant more than a regular user has, so that anyone with one of
+// them can only be managed by a system admin.
+var privilegedSystemRoles = []string{
+	model.SYSTEM_ADMIN_ROLE_ID,
+	model.SYSTEM_READ_ONLY_ADMIN_ROLE_ID,
+	model.SYSTEM_USER_MANAGER_ROLE_ID,
+}
+
+// isPrivilegedSystemUser returns true if the given user has a privileged system role or if they couldn't be loaded.
+func (a *App) isPrivilegedSystemUser(userId string) bool {
+	user, err := a.GetUser(userId)
+	if err != nil {
+		return true
+	}
+
+	for _, role := range privilegedSystemRoles {
+		if user.IsInRole(role) {
+			return true
+		}
+	}
+
 	return false
 }
 
@@ -142,6 +190,10 @@ func (a *App) HasPermissionToChannel(askingUserId string, channelId string, perm
 		return false
 	}
 
+	if a.userIsModeratedInChannel(askingUserId, channelId, model.ChannelModeratedPermissionFor(permission.Id)) {
+		return false
+	}
+
 	channelMember, err := a.GetChannelMember(channelId, askingUserId)
 	if err == nil {
 		roles := channelMember.GetRoles()
@@ -160,6 +212,14 @@ func (a *App) HasPermissionToChannel(askingUserId string, channelId string, perm
 }
 
 func (a *App) HasPermissionToChannelByPost(askingUserId string, postId string, permission *model.Permission) bool {
+	if name := model.ChannelModeratedPermissionFor(permission.Id); name != "" {
+		if result := <-a.Srv.Store.Channel().GetForPost(postId); result.Err == nil {
+			if a.userIsModeratedInChannel(askingUserId, result.Data.(*model.Channel).Id, name) {
+				return false
+			}
+		}
+	}
+
 	var channelMember *model.ChannelMember
 	if result := <-a.Srv.Store.Channel().GetMemberForPost(postId, askingUserId); result.Err == nil {
 		channelMember = result.Data.(*model.ChannelMember)
@@ -0,0 +1,173 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// bootLoader loads each section of the boot data concurrently, recording how long each one took and the error for
+// any that failed.
+type bootLoader struct {
+	app *App
+	wg  sync.WaitGroup
+
+	lock    sync.Mutex
+	errors  map[string]*model.AppError
+	timings map[string]time.Duration
+}
+
+func newBootLoader(a *App) *bootLoader {
+	return &bootLoader{
+		app:     a,
+		errors:  make(map[string]*model.AppError),
+		timings: make(map[string]time.Duration),
+	}
+}
+
+// load runs f in the background. Sections can be loaded from within another section's f, such as when they depend
+// on it.
+func (l *bootLoader) load(section string, f func() *model.AppError) {
+	l.wg.Add(1)
+
+	l.app.Go(func() {
+		defer l.wg.Done()
+
+		start := time.Now()
+		err := f()
+		elapsed := time.Since(start)
+
+		l.lock.Lock()
+		defer l.lock.Unlock()
+
+		l.timings[section] = elapsed
+		if err != nil {
+			l.errors[section] = err
+		}
+	})
+}
+
+// wait blocks until every section has been loaded.
+func (l *bootLoader) wait() {
+	l.wg.Wait()
+}
+
+// GetBootData loads everything that a client needs when it starts for the session's user, sanitized the same way
+// as when it's requested from each of the individual endpoints. A section that fails to load is left out and its
+// error is returned alongside the others. The time taken to load each section is also returned.
+func (a *App) GetBootData(session model.Session) (*model.BootData, map[string]time.Duration) {
+	userId := session.UserId
+	isSystemAdmin := a.SessionHasPermissionTo(session, model.PERMISSION_MANAGE_SYSTEM)
+
+	boot := &model.BootData{
+		Channels:       make(map[string]*model.ChannelList),
+		ChannelMembers: make(map[string]*model.ChannelMembers),
+	}
+	var channelsLock sync.Mutex
+
+	loader := newBootLoader(a)
+
+	loader.load(model.BOOT_SECTION_USER, func() *model.AppError {
+		user, err := a.GetUser(userId)
+		if err != nil {
+			return err
+		}
+
+		user.Sanitize(map[string]bool{})
+		if err := a.SetUserAttributesOnProfiles([]*model.User{user}, userId, isSystemAdmin); err != nil {
+			return err
+		}
+
+		boot.User = user
+		return nil
+	})
+
+	loader.load(model.BOOT_SECTION_TEAMS, func() *model.AppError {
+		teams, err := a.GetTeamsForUser(userId)
+		if err != nil {
+			return err
+		}
+
+		boot.Teams = a.SanitizeTeams(session, teams)
+
+		// Channels are loaded for every team once they're known
+		loader.load(model.BOOT_SECTION_CHANNELS, func() *model.AppError {
+			for _, team := range teams {
+				channels, err := a.GetChannelsForUser(team.Id, userId)
+				if err != nil {
+					return err
+				}
+
+				channelsLock.Lock()
+				boot.Channels[team.Id] = channels
+				channelsLock.Unlock()
+			}
+
+			return nil
+		})
+
+		loader.load(model.BOOT_SECTION_CHANNEL_MEMBERS, func() *model.AppError {
+			for _, team := range teams {
+				members, err := a.GetChannelMembersForUser(team.Id, userId)
+				if err != nil {
+					return err
+				}
+
+				channelsLock.Lock()
+				boot.ChannelMembers[team.Id] = members
+				channelsLock.Unlock()
+			}
+
+			return nil
+		})
+
+		return nil
+	})
+
+	loader.load(model.BOOT_SECTION_TEAM_MEMBERS, func() *model.AppError {
+		members, err := a.GetTeamMembersForUser(userId)
+		if err != nil {
+			return err
+		}
+
+		boot.TeamMembers = members
+		return nil
+	})
+
+	loader.load(model.BOOT_SECTION_PREFERENCES, func() *model.AppError {
+		preferences, err := a.GetPreferencesForUser(userId)
+		if err != nil {
+			return err
+		}
+
+		boot.Preferences = preferences
+		return nil
+	})
+
+	loader.load(model.BOOT_SECTION_LICENSE, func() *model.AppError {
+		if isSystemAdmin {
+			boot.License = a.ClientLicense()
+		} else {
+			boot.License = a.GetSanitizedClientLicense()
+		}
+
+		return nil
+	})
+
+	loader.load(model.BOOT_SECTION_CONFIG, func() *model.AppError {
+		boot.Config = a.ClientConfigWithComputed()
+		return nil
+	})
+
+	loader.wait()
+
+	if len(loader.errors) > 0 {
+		boot.Errors = loader.errors
+	}
+
+	return boot, loader.timings
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestBootLoader(t *testing.T) {
+	loader := newBootLoader(&App{})
+
+	var loaded int32
+	loader.load("works", func() *model.AppError {
+		atomic.AddInt32(&loaded, 1)
+
+		loader.load("depends", func() *model.AppError {
+			atomic.AddInt32(&loaded, 1)
+			return nil
+		})
+
+		return nil
+	})
+
+	loader.load("fails", func() *model.AppError {
+		return model.NewAppError("TestBootLoader", "app.boot.test.app_error", nil, "", http.StatusInternalServerError)
+	})
+
+	loader.wait()
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&loaded), "should have loaded every section despite the error")
+
+	require.Len(t, loader.errors, 1)
+	assert.Equal(t, "app.boot.test.app_error", loader.errors["fails"].Id)
+
+	assert.Len(t, loader.timings, 3)
+	assert.Contains(t, loader.timings, "depends")
+}
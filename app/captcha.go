@@ -0,0 +1,182 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const LOGIN_FAILURE_CACHE_SIZE = 50000
+
+// CaptchaVerifier checks the token that a client got for solving a CAPTCHA with the service that it was solved on.
+type CaptchaVerifier interface {
+	VerifyCaptcha(token, remoteAddress string) (bool, *model.AppError)
+}
+
+// recaptchaVerifier verifies tokens with reCAPTCHA's siteverify API, or with any other service that has one like it.
+type recaptchaVerifier struct {
+	client    *http.Client
+	verifyURL string
+	secretKey string
+}
+
+type recaptchaVerifyResponse struct {
+	Success    bool     `json:"success"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+func (v *recaptchaVerifier) VerifyCaptcha(token, remoteAddress string) (bool, *model.AppError) {
+	resp, err := v.client.PostForm(v.verifyURL, url.Values{
+		"secret":   {v.secretKey},
+		"response": {token},
+		"remoteip": {remoteAddress},
+	})
+	if err != nil {
+		return false, model.NewAppError("VerifyCaptcha", "app.captcha.verify.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+	defer consumeAndClose(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		return false, model.NewAppError("VerifyCaptcha", "app.captcha.verify.app_error", nil, "status="+resp.Status, http.StatusInternalServerError)
+	}
+
+	var verifyResponse recaptchaVerifyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&verifyResponse); err != nil {
+		return false, model.NewAppError("VerifyCaptcha", "app.captcha.verify.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	return verifyResponse.Success, nil
+}
+
+// pluginCaptchaVerifier verifies tokens with the VerifyCaptcha hook of a plugin, so that self-hosted CAPTCHA services
+// can be used.
+type pluginCaptchaVerifier struct {
+	app      *App
+	pluginId string
+}
+
+func (v *pluginCaptchaVerifier) VerifyCaptcha(token, remoteAddress string) (bool, *model.AppError) {
+	if !v.app.PluginsReady() {
+		return false, model.NewAppError("VerifyCaptcha", "app.captcha.plugin_unavailable.app_error", map[string]interface{}{"PluginId": v.pluginId}, "", http.StatusInternalServerError)
+	}
+
+	verified, appErr, err := v.app.PluginEnv.HooksForPlugin(v.pluginId).VerifyCaptcha(token, remoteAddress)
+	if err != nil {
+		return false, model.NewAppError("VerifyCaptcha", "app.captcha.plugin_unavailable.app_error", map[string]interface{}{"PluginId": v.pluginId}, err.Error(), http.StatusInternalServerError)
+	}
+
+	return verified, appErr
+}
+
+func (a *App) getCaptchaVerifier() CaptchaVerifier {
+	if a.captchaVerifier != nil {
+		return a.captchaVerifier
+	}
+
+	settings := a.Config().CaptchaSettings
+	if *settings.Provider == model.CAPTCHA_PROVIDER_PLUGIN {
+		return &pluginCaptchaVerifier{app: a, pluginId: *settings.PluginId}
+	}
+
+	// The verify URL is set by an admin and may be a self-hosted service on the local network
+	client := a.HTTPClient(true)
+	client.Timeout = time.Duration(*settings.VerifyTimeoutSeconds) * time.Second
+
+	return &recaptchaVerifier{
+		client:    client,
+		verifyURL: *settings.VerifyURL,
+		secretKey: *settings.SecretKey,
+	}
+}
+
+// loginFailureKeys returns the keys that failed logins are counted under for the IP address and for the user. Users
+// are counted by what they're logged in with rather than looked up, so that failures for accounts that don't exist
+// are counted too.
+func loginFailureKeys(id, loginId, ipAddress string) (string, string) {
+	userKey := "login:" + strings.ToLower(loginId)
+	if id != "" {
+		userKey = "user:" + id
+	}
+
+	return "ip:" + ipAddress, userKey
+}
+
+func (a *App) loginFailureCount(key string) int {
+	if count, ok := a.loginFailures.Get(key); ok {
+		return count.(int)
+	}
+
+	return 0
+}
+
+// IsLoginCaptchaRequired returns true if enough logins have failed from the IP address or for the user that a
+// CAPTCHA has to be solved before they can log in.
+func (a *App) IsLoginCaptchaRequired(id, loginId, ipAddress string) bool {
+	settings := a.Config().CaptchaSettings
+	if !*settings.Enable {
+		return false
+	}
+
+	ipKey, userKey := loginFailureKeys(id, loginId, ipAddress)
+
+	if perIp := *settings.LoginFailuresPerIp; perIp > 0 && a.loginFailureCount(ipKey) >= perIp {
+		return true
+	}
+
+	if perUser := *settings.LoginFailuresPerUser; perUser > 0 && a.loginFailureCount(userKey) >= perUser {
+		return true
+	}
+
+	return false
+}
+
+// CheckLoginCaptcha returns an error if a CAPTCHA has to be solved before logging in and the token that the client
+// got for solving it is missing or doesn't verify. Once one has been solved, the failed logins that made it needed
+// are forgotten.
+func (a *App) CheckLoginCaptcha(id, loginId, ipAddress, token string) *model.AppError {
+	if !a.IsLoginCaptchaRequired(id, loginId, ipAddress) {
+		return nil
+	}
+
+	if token == "" {
+		return model.NewAppError("CheckLoginCaptcha", "api.user.login.captcha_required.app_error", nil, "ip_address="+ipAddress, http.StatusUnauthorized)
+	}
+
+	verified, err := a.getCaptchaVerifier().VerifyCaptcha(token, ipAddress)
+	if err != nil {
+		return err
+	} else if !verified {
+		return model.NewAppError("CheckLoginCaptcha", "api.user.login.captcha_invalid.app_error", nil, "ip_address="+ipAddress, http.StatusUnauthorized)
+	}
+
+	ipKey, userKey := loginFailureKeys(id, loginId, ipAddress)
+	a.loginFailures.Remove(ipKey)
+	a.loginFailures.Remove(userKey)
+
+	return nil
+}
+
+// RecordLoginFailure counts a failed login from the IP address and for the user towards needing a CAPTCHA to log in.
+func (a *App) RecordLoginFailure(id, loginId, ipAddress string) {
+	settings := a.Config().CaptchaSettings
+	if !*settings.Enable {
+		return
+	}
+
+	window := int64(*settings.FailureWindowMinutes * 60)
+	ipKey, userKey := loginFailureKeys(id, loginId, ipAddress)
+
+	a.loginFailuresLock.Lock()
+	defer a.loginFailuresLock.Unlock()
+
+	for _, key := range []string{ipKey, userKey} {
+		a.loginFailures.AddWithExpiresInSecs(key, a.loginFailureCount(key)+1, window)
+	}
+}
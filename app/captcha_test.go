@@ -0,0 +1,183 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/plugin/plugintest"
+)
+
+type mockCaptchaVerifier struct {
+	tokens []string
+	err    *model.AppError
+}
+
+func (v *mockCaptchaVerifier) VerifyCaptcha(token, remoteAddress string) (bool, *model.AppError) {
+	v.tokens = append(v.tokens, token)
+	return token == "valid", v.err
+}
+
+func TestCheckLoginCaptcha(t *testing.T) {
+	th := Setup()
+	defer th.TearDown()
+
+	verifier := &mockCaptchaVerifier{}
+	th.App.captchaVerifier = verifier
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.CaptchaSettings.Enable = true
+		*cfg.CaptchaSettings.LoginFailuresPerIp = 3
+		*cfg.CaptchaSettings.LoginFailuresPerUser = 2
+	})
+
+	t.Run("user threshold", func(t *testing.T) {
+		loginId := model.NewId()
+
+		th.App.RecordLoginFailure("", loginId, "10.0.0.1")
+		assert.False(t, th.App.IsLoginCaptchaRequired("", loginId, "10.0.0.1"))
+		assert.Nil(t, th.App.CheckLoginCaptcha("", loginId, "10.0.0.1", ""))
+
+		th.App.RecordLoginFailure("", loginId, "10.0.0.2")
+		assert.True(t, th.App.IsLoginCaptchaRequired("", loginId, "10.0.0.3"), "failures from any address should count for the user")
+		assert.True(t, th.App.IsLoginCaptchaRequired("", strings.ToUpper(loginId), "10.0.0.3"), "login ids should be counted regardless of case")
+		assert.False(t, th.App.IsLoginCaptchaRequired("", model.NewId(), "10.0.0.1"))
+
+		err := th.App.CheckLoginCaptcha("", loginId, "10.0.0.3", "")
+		require.NotNil(t, err)
+		assert.Equal(t, "api.user.login.captcha_required.app_error", err.Id)
+	})
+
+	t.Run("ip threshold", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			assert.False(t, th.App.IsLoginCaptchaRequired("", model.NewId(), "10.0.1.1"))
+			th.App.RecordLoginFailure("", model.NewId(), "10.0.1.1")
+		}
+
+		assert.True(t, th.App.IsLoginCaptchaRequired("", model.NewId(), "10.0.1.1"), "failures for any user should count for the address")
+		assert.False(t, th.App.IsLoginCaptchaRequired("", model.NewId(), "10.0.1.2"))
+	})
+
+	t.Run("verification failure", func(t *testing.T) {
+		userId := model.NewId()
+		th.App.RecordLoginFailure(userId, "", "10.0.2.1")
+		th.App.RecordLoginFailure(userId, "", "10.0.2.1")
+
+		err := th.App.CheckLoginCaptcha(userId, "", "10.0.2.1", "invalid")
+		require.NotNil(t, err)
+		assert.Equal(t, "api.user.login.captcha_invalid.app_error", err.Id)
+		assert.True(t, th.App.IsLoginCaptchaRequired(userId, "", "10.0.2.1"), "a failed CAPTCHA shouldn't reset the failures")
+
+		verifier.err = model.NewAppError("VerifyCaptcha", "app.captcha.verify.app_error", nil, "", http.StatusInternalServerError)
+		err = th.App.CheckLoginCaptcha(userId, "", "10.0.2.1", "valid")
+		verifier.err = nil
+		require.NotNil(t, err)
+		assert.Equal(t, "app.captcha.verify.app_error", err.Id)
+		assert.True(t, th.App.IsLoginCaptchaRequired(userId, "", "10.0.2.1"))
+	})
+
+	t.Run("verification resets failures", func(t *testing.T) {
+		userId := model.NewId()
+		for i := 0; i < 3; i++ {
+			th.App.RecordLoginFailure(userId, "", "10.0.3.1")
+		}
+
+		assert.Nil(t, th.App.CheckLoginCaptcha(userId, "", "10.0.3.1", "valid"))
+		assert.Equal(t, "valid", verifier.tokens[len(verifier.tokens)-1])
+
+		assert.False(t, th.App.IsLoginCaptchaRequired(userId, "", "10.0.3.1"))
+		assert.False(t, th.App.IsLoginCaptchaRequired(model.NewId(), "", "10.0.3.1"))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.CaptchaSettings.Enable = false })
+		defer th.App.UpdateConfig(func(cfg *model.Config) { *cfg.CaptchaSettings.Enable = true })
+
+		for i := 0; i < 3; i++ {
+			th.App.RecordLoginFailure("", "disabled", "10.0.4.1")
+		}
+		assert.False(t, th.App.IsLoginCaptchaRequired("", "disabled", "10.0.4.1"))
+		assert.Nil(t, th.App.CheckLoginCaptcha("", "disabled", "10.0.4.1", ""))
+	})
+}
+
+func TestCaptchaVerifiers(t *testing.T) {
+	th := Setup()
+	defer th.TearDown()
+
+	t.Run("recaptcha", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Nil(t, r.ParseForm())
+			assert.Equal(t, "secret", r.PostForm.Get("secret"))
+			assert.Equal(t, "10.0.0.1", r.PostForm.Get("remoteip"))
+
+			if r.PostForm.Get("response") == "valid" {
+				w.Write([]byte(`{"success": true}`))
+			} else {
+				w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-response"]}`))
+			}
+		}))
+		defer server.Close()
+
+		th.App.UpdateConfig(func(cfg *model.Config) {
+			*cfg.CaptchaSettings.Provider = model.CAPTCHA_PROVIDER_RECAPTCHA
+			*cfg.CaptchaSettings.SecretKey = "secret"
+			*cfg.CaptchaSettings.VerifyURL = server.URL
+		})
+
+		verified, err := th.App.getCaptchaVerifier().VerifyCaptcha("valid", "10.0.0.1")
+		assert.Nil(t, err)
+		assert.True(t, verified)
+
+		verified, err = th.App.getCaptchaVerifier().VerifyCaptcha("invalid", "10.0.0.1")
+		assert.Nil(t, err)
+		assert.False(t, verified)
+
+		server.Close()
+		_, err = th.App.getCaptchaVerifier().VerifyCaptcha("valid", "10.0.0.1")
+		require.NotNil(t, err)
+		assert.Equal(t, "app.captcha.verify.app_error", err.Id)
+	})
+
+	t.Run("plugin", func(t *testing.T) {
+		var hooks plugintest.Hooks
+		hooks.On("OnActivate", mock.Anything).Return(nil)
+		hooks.On("OnDeactivate").Return(nil)
+		hooks.On("VerifyCaptcha", "valid", "10.0.0.1").Return(true, nil)
+		hooks.On("VerifyCaptcha", "invalid", "10.0.0.1").Return(false, nil)
+
+		th.InstallPlugin(&model.Manifest{Id: "captcha"}, &hooks)
+		require.Nil(t, th.App.EnablePlugin("captcha"))
+
+		// Ideally, we would wait for the websocket activation event instead of just sleeping.
+		time.Sleep(500 * time.Millisecond)
+
+		th.App.UpdateConfig(func(cfg *model.Config) {
+			*cfg.CaptchaSettings.Provider = model.CAPTCHA_PROVIDER_PLUGIN
+			*cfg.CaptchaSettings.PluginId = "captcha"
+		})
+
+		verified, err := th.App.getCaptchaVerifier().VerifyCaptcha("valid", "10.0.0.1")
+		assert.Nil(t, err)
+		assert.True(t, verified)
+
+		verified, err = th.App.getCaptchaVerifier().VerifyCaptcha("invalid", "10.0.0.1")
+		assert.Nil(t, err)
+		assert.False(t, verified)
+
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.CaptchaSettings.PluginId = "missing" })
+		_, err = th.App.getCaptchaVerifier().VerifyCaptcha("valid", "10.0.0.1")
+		require.NotNil(t, err)
+		assert.Equal(t, "app.captcha.plugin_unavailable.app_error", err.Id)
+	})
+}
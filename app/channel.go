@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/mattermost/mattermost-server/mlog"
 	"github.com/mattermost/mattermost-server/model"
@@ -15,6 +16,8 @@ import (
 	"github.com/mattermost/mattermost-server/utils"
 )
 
+const CHANNEL_MEMBER_COUNTS_RECONCILE_BATCH_SIZE = 1000
+
 func (a *App) CreateDefaultChannels(teamId string) ([]*model.Channel, *model.AppError) {
 	townSquare := &model.Channel{DisplayName: utils.T("api.channel.create_default_channels.town_square"), Name: "town-square", Type: model.CHANNEL_OPEN, TeamId: teamId}
 
@@ -44,6 +47,11 @@ func (a *App) JoinDefaultChannels(teamId string, user *model.User, channelRole s
 		}
 	}
 
+	actorId := user.Id
+	if requestor != nil {
+		actorId = requestor.Id
+	}
+
 	if result := <-a.Srv.Store.Channel().GetByName(teamId, "town-square", true); result.Err != nil {
 		err = result.Err
 	} else {
@@ -53,13 +61,13 @@ func (a *App) JoinDefaultChannels(teamId string, user *model.User, channelRole s
 			ChannelId:   townSquare.Id,
 			UserId:      user.Id,
 			Roles:       channelRole,
-			NotifyProps: model.GetDefaultChannelNotifyProps(),
+			NotifyProps: a.defaultChannelNotifyProps(),
 		}
 
 		if cmResult := <-a.Srv.Store.Channel().SaveMember(cm); cmResult.Err != nil {
 			err = cmResult.Err
 		}
-		if result := <-a.Srv.Store.ChannelMemberHistory().LogJoinEvent(user.Id, townSquare.Id, model.GetMillis()); result.Err != nil {
+		if result := <-a.Srv.Store.ChannelMemberHistory().LogJoinEvent(user.Id, townSquare.Id, model.GetMillis(), actorId); result.Err != nil {
 			mlog.Warn(fmt.Sprintf("Failed to update ChannelMemberHistory table %v", result.Err))
 		}
 
@@ -81,34 +89,69 @@ func (a *App) JoinDefaultChannels(teamId string, user *model.User, channelRole s
 	if result := <-a.Srv.Store.Channel().GetByName(teamId, "off-topic", true); result.Err != nil {
 		err = result.Err
 	} else if offTopic := result.Data.(*model.Channel); offTopic.Type == model.CHANNEL_OPEN {
-
-		cm := &model.ChannelMember{
-			ChannelId:   offTopic.Id,
-			UserId:      user.Id,
-			Roles:       channelRole,
-			NotifyProps: model.GetDefaultChannelNotifyProps(),
+		if joinErr := a.joinDefaultChannel(user, offTopic, channelRole, requestor); joinErr != nil {
+			err = joinErr
 		}
+	}
 
-		if cmResult := <-a.Srv.Store.Channel().SaveMember(cm); cmResult.Err != nil {
-			err = cmResult.Err
-		}
-		if result := <-a.Srv.Store.ChannelMemberHistory().LogJoinEvent(user.Id, offTopic.Id, model.GetMillis()); result.Err != nil {
-			mlog.Warn(fmt.Sprintf("Failed to update ChannelMemberHistory table %v", result.Err))
-		}
+	if result := <-a.Srv.Store.Team().GetDefaultChannels(teamId); result.Err != nil {
+		err = result.Err
+	} else {
+		for _, channelId := range result.Data.([]string) {
+			channel, getErr := a.GetChannel(channelId)
+			if getErr != nil {
+				err = getErr
+				continue
+			}
 
-		if requestor == nil {
-			if err := a.postJoinChannelMessage(user, offTopic); err != nil {
-				mlog.Error(fmt.Sprint("Failed to post join/leave message", err))
+			// Skip channels that have been archived or made private since they were added to the list
+			if channel.TeamId != teamId || channel.Type != model.CHANNEL_OPEN || channel.DeleteAt != 0 || channel.Name == model.DEFAULT_CHANNEL || channel.Name == "off-topic" {
+				continue
 			}
-		} else {
-			if err := a.PostAddToChannelMessage(requestor, user, offTopic, ""); err != nil {
-				mlog.Error(fmt.Sprint("Failed to post join/leave message", err))
+
+			if joinErr := a.joinDefaultChannel(user, channel, channelRole, requestor); joinErr != nil {
+				err = joinErr
 			}
 		}
+	}
 
-		a.InvalidateCacheForChannelMembers(result.Data.(*model.Channel).Id)
+	return err
+}
+
+func (a *App) joinDefaultChannel(user *model.User, channel *model.Channel, channelRole string, requestor *model.User) *model.AppError {
+	var err *model.AppError
+
+	cm := &model.ChannelMember{
+		ChannelId:   channel.Id,
+		UserId:      user.Id,
+		Roles:       channelRole,
+		NotifyProps: a.defaultChannelNotifyProps(),
+	}
+
+	if cmResult := <-a.Srv.Store.Channel().SaveMember(cm); cmResult.Err != nil {
+		err = cmResult.Err
+	}
+
+	actorId := user.Id
+	if requestor != nil {
+		actorId = requestor.Id
+	}
+	if result := <-a.Srv.Store.ChannelMemberHistory().LogJoinEvent(user.Id, channel.Id, model.GetMillis(), actorId); result.Err != nil {
+		mlog.Warn(fmt.Sprintf("Failed to update ChannelMemberHistory table %v", result.Err))
 	}
 
+	if requestor == nil {
+		if err := a.postJoinChannelMessage(user, channel); err != nil {
+			mlog.Error(fmt.Sprint("Failed to post join/leave message", err))
+		}
+	} else {
+		if err := a.PostAddToChannelMessage(requestor, user, channel, ""); err != nil {
+			mlog.Error(fmt.Sprint("Failed to post join/leave message", err))
+		}
+	}
+
+	a.InvalidateCacheForChannelMembers(channel.Id)
+
 	return err
 }
 
@@ -157,23 +200,35 @@ func (a *App) CreateChannelWithUser(channel *model.Channel, userId string) (*mod
 }
 
 func (a *App) CreateChannel(channel *model.Channel, addMember bool) (*model.Channel, *model.AppError) {
+	if err := a.checkChannelHeaderAndPurposeLength(channel, &model.Channel{}); err != nil {
+		return nil, err
+	}
+
+	if channel.EnableFeed {
+		if err := a.checkChannelFeedAllowed(channel); err != nil {
+			return nil, err
+		}
+	}
+
 	if result := <-a.Srv.Store.Channel().Save(channel, *a.Config().TeamSettings.MaxChannelsPerTeam); result.Err != nil {
 		return nil, result.Err
 	} else {
 		sc := result.Data.(*model.Channel)
 
+		a.removeChannelNameFromHistory(sc)
+
 		if addMember {
 			cm := &model.ChannelMember{
 				ChannelId:   sc.Id,
 				UserId:      channel.CreatorId,
 				Roles:       model.CHANNEL_USER_ROLE_ID + " " + model.CHANNEL_ADMIN_ROLE_ID,
-				NotifyProps: model.GetDefaultChannelNotifyProps(),
+				NotifyProps: a.defaultChannelNotifyProps(),
 			}
 
 			if cmresult := <-a.Srv.Store.Channel().SaveMember(cm); cmresult.Err != nil {
 				return nil, cmresult.Err
 			}
-			if result := <-a.Srv.Store.ChannelMemberHistory().LogJoinEvent(channel.CreatorId, sc.Id, model.GetMillis()); result.Err != nil {
+			if result := <-a.Srv.Store.ChannelMemberHistory().LogJoinEvent(channel.CreatorId, sc.Id, model.GetMillis(), channel.CreatorId); result.Err != nil {
 				mlog.Warn(fmt.Sprintf("Failed to update ChannelMemberHistory table %v", result.Err))
 			}
 
@@ -217,6 +272,14 @@ func (a *App) createDirectChannel(userId string, otherUserId string) (*model.Cha
 		return nil, model.NewAppError("CreateDirectChannel", "api.channel.create_direct_channel.invalid_user.app_error", nil, otherUserId, http.StatusBadRequest)
 	}
 
+	if err := a.checkCanCreateDirectChannel(userId, otherUserId); err != nil {
+		// Blocking a user only stops new conversations, so a direct channel that already exists is still returned
+		if result := <-a.Srv.Store.Channel().GetByName("", model.GetDMNameFromIds(userId, otherUserId), true); result.Err == nil {
+			return result.Data.(*model.Channel), model.NewAppError("CreateDirectChannel", store.CHANNEL_EXISTS_ERROR, nil, "", http.StatusBadRequest)
+		}
+		return nil, err
+	}
+
 	if result := <-a.Srv.Store.Channel().CreateDirectChannel(userId, otherUserId); result.Err != nil {
 		if result.Err.Id == store.CHANNEL_EXISTS_ERROR {
 			return result.Data.(*model.Channel), result.Err
@@ -226,10 +289,10 @@ func (a *App) createDirectChannel(userId string, otherUserId string) (*model.Cha
 	} else {
 		channel := result.Data.(*model.Channel)
 
-		if result := <-a.Srv.Store.ChannelMemberHistory().LogJoinEvent(userId, channel.Id, model.GetMillis()); result.Err != nil {
+		if result := <-a.Srv.Store.ChannelMemberHistory().LogJoinEvent(userId, channel.Id, model.GetMillis(), userId); result.Err != nil {
 			mlog.Warn(fmt.Sprintf("Failed to update ChannelMemberHistory table %v", result.Err))
 		}
-		if result := <-a.Srv.Store.ChannelMemberHistory().LogJoinEvent(otherUserId, channel.Id, model.GetMillis()); result.Err != nil {
+		if result := <-a.Srv.Store.ChannelMemberHistory().LogJoinEvent(otherUserId, channel.Id, model.GetMillis(), userId); result.Err != nil {
 			mlog.Warn(fmt.Sprintf("Failed to update ChannelMemberHistory table %v", result.Err))
 		}
 
@@ -306,6 +369,7 @@ func (a *App) createGroupChannel(userIds []string, creatorId string) (*model.Cha
 		Name:        model.GetGroupNameFromUserIds(userIds),
 		DisplayName: model.GetGroupDisplayNameFromUsers(users, true),
 		Type:        model.CHANNEL_GROUP,
+		CreatorId:   creatorId,
 	}
 
 	if result := <-a.Srv.Store.Channel().Save(group, *a.Config().TeamSettings.MaxChannelsPerTeam); result.Err != nil {
@@ -328,7 +392,7 @@ func (a *App) createGroupChannel(userIds []string, creatorId string) (*model.Cha
 			if result := <-a.Srv.Store.Channel().SaveMember(cm); result.Err != nil {
 				return nil, result.Err
 			}
-			if result := <-a.Srv.Store.ChannelMemberHistory().LogJoinEvent(user.Id, channel.Id, model.GetMillis()); result.Err != nil {
+			if result := <-a.Srv.Store.ChannelMemberHistory().LogJoinEvent(user.Id, channel.Id, model.GetMillis(), creatorId); result.Err != nil {
 				mlog.Warn(fmt.Sprintf("Failed to update ChannelMemberHistory table %v", result.Err))
 			}
 		}
@@ -337,18 +401,76 @@ func (a *App) createGroupChannel(userIds []string, creatorId string) (*model.Cha
 	}
 }
 
+// UpdateChannel saves the changes made to channel. Clients are only sent the fields that changed since headers and
+// purposes can be long.
 func (a *App) UpdateChannel(channel *model.Channel) (*model.Channel, *model.AppError) {
-	if result := <-a.Srv.Store.Channel().Update(channel); result.Err != nil {
+	old, err := a.GetChannel(channel.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.checkChannelHeaderAndPurposeLength(channel, old); err != nil {
+		return nil, err
+	}
+
+	if err := a.checkChannelIconEmoji(channel, old); err != nil {
+		return nil, err
+	}
+
+	var result store.StoreResult
+	var messageWs *model.WebSocketEvent
+	var outboxEvent *model.WebSocketOutboxEvent
+	if *a.Config().ServiceSettings.ReliableWebsocketEvents {
+		// The changes are worked out before the update so that the event can be saved with it. The store sets the
+		// channel's UpdateAt again when saving it, so the one in the event can be a moment earlier.
+		channel.PreUpdate()
+		messageWs = a.newChannelUpdatedEvent(channel, old)
+		outboxEvent = model.NewWebSocketOutboxEvent(messageWs)
+		result = <-a.Srv.Store.Channel().UpdateWithWebSocketEvent(channel, outboxEvent)
+	} else {
+		result = <-a.Srv.Store.Channel().Update(channel)
+	}
+	if result.Err != nil {
 		return nil, result.Err
+	}
+
+	a.InvalidateCacheForChannel(channel)
+
+	if channel.Name != old.Name {
+		a.InvalidateCacheForChannel(old)
+		a.updateChannelNameHistory(channel, old.Name)
+	}
+
+	if outboxEvent != nil {
+		a.publishWebSocketOutboxEvent(outboxEvent.Id, messageWs)
 	} else {
-		a.InvalidateCacheForChannel(channel)
+		a.Publish(a.newChannelUpdatedEvent(channel, old))
+	}
 
-		messageWs := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_CHANNEL_UPDATED, "", channel.Id, "", nil)
-		messageWs.Add("channel", channel.ToJson())
-		a.Publish(messageWs)
+	return channel, nil
+}
 
-		return channel, nil
+func (a *App) newChannelUpdatedEvent(channel *model.Channel, old *model.Channel) *model.WebSocketEvent {
+	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_CHANNEL_UPDATED, "", channel.Id, "", nil)
+	message.Add("changes", model.StringInterfaceToJson(channel.Changes(old)))
+	return message
+}
+
+// checkChannelHeaderAndPurposeLength returns an error if the header or purpose of channel is longer than the
+// configured maximum. They're only checked if they've changed from old so that lowering the maximum doesn't stop
+// other changes from being made to channels that already have longer ones.
+func (a *App) checkChannelHeaderAndPurposeLength(channel *model.Channel, old *model.Channel) *model.AppError {
+	maxHeader := *a.Config().TeamSettings.MaxChannelHeaderLength
+	if channel.Header != old.Header && utf8.RuneCountInString(channel.Header) > maxHeader {
+		return model.NewAppError("checkChannelHeaderAndPurposeLength", "app.channel.header_too_long.app_error", map[string]interface{}{"MaxLength": maxHeader}, "id="+channel.Id, http.StatusBadRequest)
 	}
+
+	maxPurpose := *a.Config().TeamSettings.MaxChannelPurposeLength
+	if channel.Purpose != old.Purpose && utf8.RuneCountInString(channel.Purpose) > maxPurpose {
+		return model.NewAppError("checkChannelHeaderAndPurposeLength", "app.channel.purpose_too_long.app_error", map[string]interface{}{"MaxLength": maxPurpose}, "id="+channel.Id, http.StatusBadRequest)
+	}
+
+	return nil
 }
 
 func (a *App) UpdateChannelPrivacy(oldChannel *model.Channel, user *model.User) (*model.Channel, *model.AppError) {
@@ -371,19 +493,16 @@ func (a *App) UpdateChannelPrivacy(oldChannel *model.Channel, user *model.User)
 }
 
 func (a *App) postChannelPrivacyMessage(user *model.User, channel *model.Channel) *model.AppError {
-	privacy := (map[string]string{
-		model.CHANNEL_OPEN:    "private_to_public",
-		model.CHANNEL_PRIVATE: "public_to_private",
-	})[channel.Type]
 	post := &model.Post{
 		ChannelId: channel.Id,
-		Message:   utils.T("api.channel.change_channel_privacy." + privacy),
 		Type:      model.POST_CHANGE_CHANNEL_PRIVACY,
 		UserId:    user.Id,
 		Props: model.StringInterface{
 			"username": user.Username,
+			"new_type": channel.Type,
 		},
 	}
+	post.Message = RenderSystemMessage(post, utils.T)
 
 	if _, err := a.CreatePost(post, channel, false); err != nil {
 		return model.NewAppError("postChannelPrivacyMessage", "api.channel.post_channel_privacy_message.error", nil, err.Error(), http.StatusInternalServerError)
@@ -392,15 +511,30 @@ func (a *App) postChannelPrivacyMessage(user *model.User, channel *model.Channel
 	return nil
 }
 
+// RestoreChannel unarchives the channel, cancelling its permanent deletion if it was marked for it. Channels can't
+// be restored once they're due to be permanently deleted.
 func (a *App) RestoreChannel(channel *model.Channel) (*model.Channel, *model.AppError) {
+	if channel.PermanentDeleteAt != 0 && channel.PermanentDeleteAt <= model.GetMillis() {
+		return nil, model.NewAppError("RestoreChannel", "app.channel.restore_channel.permanently_deleted.app_error", nil, "id="+channel.Id, http.StatusBadRequest)
+	}
+
 	if result := <-a.Srv.Store.Channel().Restore(channel.Id, model.GetMillis()); result.Err != nil {
 		return nil, result.Err
 	} else {
+		a.InvalidateCacheForChannel(channel)
+		channel.DeleteAt = 0
+		channel.PermanentDeleteAt = 0
 		return channel, nil
 	}
 }
 
 func (a *App) PatchChannel(channel *model.Channel, patch *model.ChannelPatch, userId string) (*model.Channel, *model.AppError) {
+	if patch.EnableFeed != nil && *patch.EnableFeed && !channel.EnableFeed {
+		if err := a.checkChannelFeedAllowed(channel); err != nil {
+			return nil, err
+		}
+	}
+
 	oldChannelDisplayName := channel.DisplayName
 	oldChannelHeader := channel.Header
 	oldChannelPurpose := channel.Purpose
@@ -433,6 +567,12 @@ func (a *App) PatchChannel(channel *model.Channel, patch *model.ChannelPatch, us
 }
 
 func (a *App) UpdateChannelMemberRoles(channelId string, userId string, newRoles string) (*model.ChannelMember, *model.AppError) {
+	return a.UpdateChannelMemberRolesByActor(channelId, userId, newRoles, "")
+}
+
+// UpdateChannelMemberRolesByActor changes the member's roles in the channel and tells everyone in the channel, along
+// with who changed them. actorId is empty if the change wasn't made by a user.
+func (a *App) UpdateChannelMemberRolesByActor(channelId string, userId string, newRoles string, actorId string) (*model.ChannelMember, *model.AppError) {
 	var member *model.ChannelMember
 	var err *model.AppError
 	if member, err = a.GetChannelMember(channelId, userId); err != nil {
@@ -449,10 +589,55 @@ func (a *App) UpdateChannelMemberRoles(channelId string, userId string, newRoles
 		return nil, result.Err
 	}
 
+	// Also drops the channel members cached for the user's permission checks, on every server in the cluster
 	a.InvalidateCacheForUser(userId)
+	a.sendChannelMemberRolesUpdatedEvent(member, actorId)
+
 	return member, nil
 }
 
+// UpdateChannelMembersRoles gives all of the users the same roles in the channel. Either all of them are changed or,
+// if any of the users isn't a member, none are.
+func (a *App) UpdateChannelMembersRoles(channelId string, userIds []string, newRoles string, actorId string) ([]*model.ChannelMember, *model.AppError) {
+	if err := a.CheckRolesExist(strings.Fields(newRoles)); err != nil {
+		return nil, err
+	}
+
+	userIds = utils.RemoveDuplicatesFromStringArray(userIds)
+
+	members := make([]*model.ChannelMember, 0, len(userIds))
+	for _, userId := range userIds {
+		member, err := a.GetChannelMember(channelId, userId)
+		if err != nil {
+			return nil, err
+		}
+
+		member.Roles = newRoles
+		members = append(members, member)
+	}
+
+	if result := <-a.Srv.Store.Channel().UpdateMembers(members); result.Err != nil {
+		return nil, result.Err
+	}
+
+	for _, member := range members {
+		a.InvalidateCacheForUser(member.UserId)
+		a.sendChannelMemberRolesUpdatedEvent(member, actorId)
+	}
+
+	return members, nil
+}
+
+// sendChannelMemberRolesUpdatedEvent tells everyone in the channel that the member's roles changed, so that the
+// member's other sessions pick up their new permissions and everyone else's member lists show them.
+func (a *App) sendChannelMemberRolesUpdatedEvent(member *model.ChannelMember, actorId string) {
+	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_CHANNEL_MEMBER_UPDATED, "", member.ChannelId, "", nil)
+	message.Add("channelMember", member.ToJson())
+	message.Add("user_id", member.UserId)
+	message.Add("actor_id", actorId)
+	a.Publish(message)
+}
+
 func (a *App) UpdateChannelMemberNotifyProps(data map[string]string, channelId string, userId string) (*model.ChannelMember, *model.AppError) {
 	var member *model.ChannelMember
 	var err *model.AppError
@@ -523,17 +708,15 @@ func (a *App) DeleteChannel(channel *model.Channel, userId string) *model.AppErr
 		}
 
 		if user != nil {
-			T := utils.GetUserTranslations(user.Locale)
-
 			post := &model.Post{
 				ChannelId: channel.Id,
-				Message:   fmt.Sprintf(T("api.channel.delete_channel.archived"), user.Username),
 				Type:      model.POST_CHANNEL_DELETED,
 				UserId:    userId,
 				Props: model.StringInterface{
 					"username": user.Username,
 				},
 			}
+			post.Message = RenderSystemMessage(post, utils.T)
 
 			if _, err := a.CreatePost(post, channel, false); err != nil {
 				mlog.Error(fmt.Sprintf("Failed to post archive message %v", err))
@@ -567,7 +750,7 @@ func (a *App) DeleteChannel(channel *model.Channel, userId string) *model.AppErr
 	return nil
 }
 
-func (a *App) addUserToChannel(user *model.User, channel *model.Channel, teamMember *model.TeamMember) (*model.ChannelMember, *model.AppError) {
+func (a *App) addUserToChannel(user *model.User, channel *model.Channel, teamMember *model.TeamMember, actorId string) (*model.ChannelMember, *model.AppError) {
 	if channel.DeleteAt > 0 {
 		return nil, model.NewAppError("AddUserToChannel", "api.channel.add_user_to_channel.deleted.app_error", nil, "", http.StatusBadRequest)
 	}
@@ -590,16 +773,19 @@ func (a *App) addUserToChannel(user *model.User, channel *model.Channel, teamMem
 	newMember := &model.ChannelMember{
 		ChannelId:   channel.Id,
 		UserId:      user.Id,
-		NotifyProps: model.GetDefaultChannelNotifyProps(),
+		NotifyProps: a.defaultChannelNotifyProps(),
 		Roles:       model.CHANNEL_USER_ROLE_ID,
 	}
+	if user.IsGuest() {
+		newMember.Roles = model.CHANNEL_GUEST_ROLE_ID
+	}
 	if result := <-a.Srv.Store.Channel().SaveMember(newMember); result.Err != nil {
 		mlog.Error(fmt.Sprintf("Failed to add member user_id=%v channel_id=%v err=%v", user.Id, channel.Id, result.Err), mlog.String("user_id", user.Id))
 		return nil, model.NewAppError("AddUserToChannel", "api.channel.add_user.to.channel.failed.app_error", nil, "", http.StatusInternalServerError)
 	}
 	a.WaitForChannelMembership(channel.Id, user.Id)
 
-	if result := <-a.Srv.Store.ChannelMemberHistory().LogJoinEvent(user.Id, channel.Id, model.GetMillis()); result.Err != nil {
+	if result := <-a.Srv.Store.ChannelMemberHistory().LogJoinEvent(user.Id, channel.Id, model.GetMillis(), actorId); result.Err != nil {
 		mlog.Warn(fmt.Sprintf("Failed to update ChannelMemberHistory table %v", result.Err))
 	}
 
@@ -609,7 +795,13 @@ func (a *App) addUserToChannel(user *model.User, channel *model.Channel, teamMem
 	return newMember, nil
 }
 
+// AddUserToChannel adds the user to the channel on behalf of the system, such as from the CLI or an import.
 func (a *App) AddUserToChannel(user *model.User, channel *model.Channel) (*model.ChannelMember, *model.AppError) {
+	return a.addUserToChannelAs(user, channel, model.CHANNEL_MEMBER_HISTORY_ACTOR_SYSTEM)
+}
+
+// addUserToChannelAs adds the user to the channel, recording actorId as who added them in the channel member history.
+func (a *App) addUserToChannelAs(user *model.User, channel *model.Channel, actorId string) (*model.ChannelMember, *model.AppError) {
 	tmchan := a.Srv.Store.Team().GetMember(channel.TeamId, user.Id)
 	var teamMember *model.TeamMember
 
@@ -622,7 +814,7 @@ func (a *App) AddUserToChannel(user *model.User, channel *model.Channel) (*model
 		}
 	}
 
-	newMember, err := a.addUserToChannel(user, channel, teamMember)
+	newMember, err := a.addUserToChannel(user, channel, teamMember, actorId)
 	if err != nil {
 		return nil, err
 	}
@@ -658,7 +850,12 @@ func (a *App) AddChannelMember(userId string, channel *model.Channel, userReques
 		}
 	}
 
-	cm, err := a.AddUserToChannel(user, channel)
+	actorId := userRequestorId
+	if actorId == "" {
+		actorId = model.CHANNEL_MEMBER_HISTORY_ACTOR_SYSTEM
+	}
+
+	cm, err := a.addUserToChannelAs(user, channel, actorId)
 	if err != nil {
 		return nil, err
 	}
@@ -722,18 +919,8 @@ func (a *App) PostUpdateChannelHeaderMessage(userId string, channel *model.Chann
 	} else {
 		user := uresult.Data.(*model.User)
 
-		var message string
-		if oldChannelHeader == "" {
-			message = fmt.Sprintf(utils.T("api.channel.post_update_channel_header_message_and_forget.updated_to"), user.Username, newChannelHeader)
-		} else if newChannelHeader == "" {
-			message = fmt.Sprintf(utils.T("api.channel.post_update_channel_header_message_and_forget.removed"), user.Username, oldChannelHeader)
-		} else {
-			message = fmt.Sprintf(utils.T("api.channel.post_update_channel_header_message_and_forget.updated_from"), user.Username, oldChannelHeader, newChannelHeader)
-		}
-
 		post := &model.Post{
 			ChannelId: channel.Id,
-			Message:   message,
 			Type:      model.POST_HEADER_CHANGE,
 			UserId:    userId,
 			Props: model.StringInterface{
@@ -742,6 +929,7 @@ func (a *App) PostUpdateChannelHeaderMessage(userId string, channel *model.Chann
 				"new_header": newChannelHeader,
 			},
 		}
+		post.Message = RenderSystemMessage(post, utils.T)
 
 		if _, err := a.CreatePost(post, channel, false); err != nil {
 			return model.NewAppError("", "api.channel.post_update_channel_header_message_and_forget.post.error", nil, err.Error(), http.StatusInternalServerError)
@@ -759,18 +947,8 @@ func (a *App) PostUpdateChannelPurposeMessage(userId string, channel *model.Chan
 	} else {
 		user := uresult.Data.(*model.User)
 
-		var message string
-		if oldChannelPurpose == "" {
-			message = fmt.Sprintf(utils.T("app.channel.post_update_channel_purpose_message.updated_to"), user.Username, newChannelPurpose)
-		} else if newChannelPurpose == "" {
-			message = fmt.Sprintf(utils.T("app.channel.post_update_channel_purpose_message.removed"), user.Username, oldChannelPurpose)
-		} else {
-			message = fmt.Sprintf(utils.T("app.channel.post_update_channel_purpose_message.updated_from"), user.Username, oldChannelPurpose, newChannelPurpose)
-		}
-
 		post := &model.Post{
 			ChannelId: channel.Id,
-			Message:   message,
 			Type:      model.POST_PURPOSE_CHANGE,
 			UserId:    userId,
 			Props: model.StringInterface{
@@ -779,6 +957,7 @@ func (a *App) PostUpdateChannelPurposeMessage(userId string, channel *model.Chan
 				"new_purpose": newChannelPurpose,
 			},
 		}
+		post.Message = RenderSystemMessage(post, utils.T)
 		if _, err := a.CreatePost(post, channel, false); err != nil {
 			return model.NewAppError("", "app.channel.post_update_channel_purpose_message.post.error", nil, err.Error(), http.StatusInternalServerError)
 		}
@@ -795,11 +974,8 @@ func (a *App) PostUpdateChannelDisplayNameMessage(userId string, channel *model.
 	} else {
 		user := uresult.Data.(*model.User)
 
-		message := fmt.Sprintf(utils.T("api.channel.post_update_channel_displayname_message_and_forget.updated_from"), user.Username, oldChannelDisplayName, newChannelDisplayName)
-
 		post := &model.Post{
 			ChannelId: channel.Id,
-			Message:   message,
 			Type:      model.POST_DISPLAYNAME_CHANGE,
 			UserId:    userId,
 			Props: model.StringInterface{
@@ -808,6 +984,7 @@ func (a *App) PostUpdateChannelDisplayNameMessage(userId string, channel *model.
 				"new_displayname": newChannelDisplayName,
 			},
 		}
+		post.Message = RenderSystemMessage(post, utils.T)
 
 		if _, err := a.CreatePost(post, channel, false); err != nil {
 			return model.NewAppError("PostUpdateChannelDisplayNameMessage", "api.channel.post_update_channel_displayname_message_and_forget.create_post.error", nil, err.Error(), http.StatusInternalServerError)
@@ -829,10 +1006,12 @@ func (a *App) GetChannel(channelId string) (*model.Channel, *model.AppError) {
 	}
 }
 
+// GetChannelByName returns the channel in a team with the given name. If there isn't one, the channel that used to
+// have that name before it was renamed is returned instead.
 func (a *App) GetChannelByName(channelName, teamId string) (*model.Channel, *model.AppError) {
 	if result := <-a.Srv.Store.Channel().GetByName(teamId, channelName, true); result.Err != nil && result.Err.Id == "store.sql_channel.get_by_name.missing.app_error" {
 		result.Err.StatusCode = http.StatusNotFound
-		return nil, result.Err
+		return a.getChannelByOldName(teamId, channelName, result.Err)
 	} else if result.Err != nil {
 		result.Err.StatusCode = http.StatusBadRequest
 		return nil, result.Err
@@ -865,7 +1044,7 @@ func (a *App) GetChannelByNameForTeamName(channelName, teamName string) (*model.
 
 	if result := <-a.Srv.Store.Channel().GetByName(team.Id, channelName, true); result.Err != nil && result.Err.Id == "store.sql_channel.get_by_name.missing.app_error" {
 		result.Err.StatusCode = http.StatusNotFound
-		return nil, result.Err
+		return a.getChannelByOldName(team.Id, channelName, result.Err)
 	} else if result.Err != nil {
 		result.Err.StatusCode = http.StatusBadRequest
 		return nil, result.Err
@@ -915,6 +1094,8 @@ func (a *App) GetPublicChannelsForTeam(teamId string, offset int, limit int) (*m
 }
 
 func (a *App) GetChannelMember(channelId string, userId string) (*model.ChannelMember, *model.AppError) {
+	a.flushChannelViewsForUser(userId)
+
 	if result := <-a.Srv.Store.Channel().GetMember(channelId, userId); result.Err != nil {
 		return nil, result.Err
 	} else {
@@ -930,6 +1111,20 @@ func (a *App) GetChannelMembersPage(channelId string, page, perPage int) (*model
 	}
 }
 
+// GetChannelMemberHistory returns a page of the memberships of the channel that were started or ended between
+// since and until, which defaults to now, along with who started and ended them.
+func (a *App) GetChannelMemberHistory(channelId string, since int64, until int64, page int, perPage int) ([]*model.ChannelMemberHistoryResult, *model.AppError) {
+	if until == 0 {
+		until = model.GetMillis()
+	}
+
+	if result := <-a.Srv.Store.ChannelMemberHistory().GetChannelHistory(channelId, since, until, page*perPage, perPage); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return result.Data.([]*model.ChannelMemberHistoryResult), nil
+	}
+}
+
 func (a *App) GetChannelMembersByIds(channelId string, userIds []string) (*model.ChannelMembers, *model.AppError) {
 	if result := <-a.Srv.Store.Channel().GetMembersByIds(channelId, userIds); result.Err != nil {
 		return nil, result.Err
@@ -939,6 +1134,8 @@ func (a *App) GetChannelMembersByIds(channelId string, userIds []string) (*model
 }
 
 func (a *App) GetChannelMembersForUser(teamId string, userId string) (*model.ChannelMembers, *model.AppError) {
+	a.flushChannelViewsForUser(userId)
+
 	if result := <-a.Srv.Store.Channel().GetMembersForUser(teamId, userId); result.Err != nil {
 		return nil, result.Err
 	} else {
@@ -954,6 +1151,35 @@ func (a *App) GetChannelMemberCount(channelId string) (int64, *model.AppError) {
 	}
 }
 
+func (a *App) GetChannelGuestCount(channelId string) (int64, *model.AppError) {
+	if result := <-a.Srv.Store.Channel().GetGuestCount(channelId); result.Err != nil {
+		return 0, result.Err
+	} else {
+		return result.Data.(int64), nil
+	}
+}
+
+// ReconcileChannelMemberCounts recounts the members and guests of every channel, fixing any counts that have drifted
+// from the channels' actual members, and returns how many channels were fixed.
+func (a *App) ReconcileChannelMemberCounts() (int64, *model.AppError) {
+	var corrected int64
+	afterChannelId := ""
+	for {
+		result := <-a.Srv.Store.Channel().ReconcileMemberCounts(afterChannelId, CHANNEL_MEMBER_COUNTS_RECONCILE_BATCH_SIZE)
+		if result.Err != nil {
+			return corrected, result.Err
+		}
+
+		batch := result.Data.(*model.ChannelMemberCountsBatch)
+		corrected += batch.Corrected
+		if batch.LastChannelId == "" {
+			return corrected, nil
+		}
+
+		afterChannelId = batch.LastChannelId
+	}
+}
+
 func (a *App) GetChannelCounts(teamId string, userId string) (*model.ChannelCounts, *model.AppError) {
 	if result := <-a.Srv.Store.Channel().GetChannelCounts(teamId, userId); result.Err != nil {
 		return nil, result.Err
@@ -963,6 +1189,8 @@ func (a *App) GetChannelCounts(teamId string, userId string) (*model.ChannelCoun
 }
 
 func (a *App) GetChannelUnread(channelId, userId string) (*model.ChannelUnread, *model.AppError) {
+	a.flushChannelViewsForUser(userId)
+
 	result := <-a.Srv.Store.Channel().GetChannelUnread(channelId, userId)
 	if result.Err != nil {
 		return nil, result.Err
@@ -976,6 +1204,49 @@ func (a *App) GetChannelUnread(channelId, userId string) (*model.ChannelUnread,
 	return channelUnread, nil
 }
 
+// GetChannelUnreadsForUser returns the unread counts of every channel that the user is a member of on a team,
+// including their direct and group message channels, or on all of their teams if teamId is blank.
+func (a *App) GetChannelUnreadsForUser(teamId, userId string) ([]*model.ChannelUnread, *model.AppError) {
+	a.flushChannelViewsForUser(userId)
+
+	result := <-a.Srv.Store.Channel().GetChannelUnreadsForUser(teamId, userId)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	channelUnreads := result.Data.([]*model.ChannelUnread)
+
+	for _, channelUnread := range channelUnreads {
+		if channelUnread.NotifyProps[model.MARK_UNREAD_NOTIFY_PROP] == model.CHANNEL_MARK_UNREAD_MENTION {
+			channelUnread.MsgCount = 0
+		}
+	}
+
+	return channelUnreads, nil
+}
+
+// publishChannelViewed tells the user's clients that they've viewed a channel along with the channel's unread
+// counts afterwards, so that the clients don't need to work them out.
+func (a *App) publishChannelViewed(channelId, userId string) {
+	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_CHANNEL_VIEWED, "", "", userId, nil)
+	message.Add("channel_id", channelId)
+
+	if view := a.getPendingChannelView(userId, channelId); view != nil {
+		// The user has read everything up to when they viewed the channel, so there's no need to write their view
+		// to the database just to read it back
+		message.Add("msg_count", 0)
+		message.Add("mention_count", 0)
+		message.Add("last_viewed_at", view.LastPostAt)
+	} else if channelUnread, err := a.GetChannelUnread(channelId, userId); err != nil {
+		mlog.Warn(fmt.Sprintf("Unable to get the unread counts of a viewed channel, channel_id=%s, user_id=%s, err=%v", channelId, userId, err))
+	} else {
+		message.Add("msg_count", channelUnread.MsgCount)
+		message.Add("mention_count", channelUnread.MentionCount)
+		message.Add("last_viewed_at", channelUnread.LastViewedAt)
+	}
+
+	a.Publish(message)
+}
+
 func (a *App) JoinChannel(channel *model.Channel, userId string) *model.AppError {
 	if channel.DeleteAt > 0 {
 		return model.NewAppError("JoinChannel", "api.channel.join_channel.already_deleted.app_error", nil, "", http.StatusBadRequest)
@@ -993,7 +1264,7 @@ func (a *App) JoinChannel(channel *model.Channel, userId string) *model.AppError
 		user := uresult.Data.(*model.User)
 
 		if channel.Type == model.CHANNEL_OPEN {
-			if _, err := a.AddUserToChannel(user, channel); err != nil {
+			if _, err := a.addUserToChannelAs(user, channel, user.Id); err != nil {
 				return err
 			}
 
@@ -1008,18 +1279,53 @@ func (a *App) JoinChannel(channel *model.Channel, userId string) *model.AppError
 	return nil
 }
 
+// IsJoinLeaveMessageEnabled returns whether system messages should be posted when users join or leave the channel.
+func (a *App) IsJoinLeaveMessageEnabled(channel *model.Channel) bool {
+	switch channel.JoinLeaveMessages {
+	case model.CHANNEL_JOIN_LEAVE_MESSAGES_ON:
+		return true
+	case model.CHANNEL_JOIN_LEAVE_MESSAGES_OFF:
+		return false
+	default:
+		return *a.Config().ServiceSettings.EnableJoinLeaveMessagesByDefault
+	}
+}
+
+// createJoinLeavePost creates a join/leave system message unless they've been turned off for the channel, in which
+// case it returns nil without creating anything.
+func (a *App) createJoinLeavePost(post *model.Post, channel *model.Channel) (*model.Post, *model.AppError) {
+	if !a.IsJoinLeaveMessageEnabled(channel) {
+		return nil, nil
+	}
+
+	rpost, err := a.CreatePost(post, channel, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Most join/leave messages don't count towards unreads, but team additions and removals do, so mark them as read
+	// for anyone who has hidden join/leave messages
+	if post.Type == model.POST_ADD_TO_TEAM || post.Type == model.POST_REMOVE_FROM_TEAM {
+		if result := <-a.Srv.Store.Channel().IncrementMsgCountForUsersWithPreference(channel.Id, model.PREFERENCE_CATEGORY_ADVANCED_SETTINGS, model.PREFERENCE_NAME_JOIN_LEAVE, "false"); result.Err != nil {
+			mlog.Error(fmt.Sprintf("Failed to update message counts for hidden join/leave message, channel_id=%v, err=%v", channel.Id, result.Err))
+		}
+	}
+
+	return rpost, nil
+}
+
 func (a *App) postJoinChannelMessage(user *model.User, channel *model.Channel) *model.AppError {
 	post := &model.Post{
 		ChannelId: channel.Id,
-		Message:   fmt.Sprintf(utils.T("api.channel.join_channel.post_and_forget"), user.Username),
 		Type:      model.POST_JOIN_CHANNEL,
 		UserId:    user.Id,
 		Props: model.StringInterface{
 			"username": user.Username,
 		},
 	}
+	post.Message = RenderSystemMessage(post, utils.T)
 
-	if _, err := a.CreatePost(post, channel, false); err != nil {
+	if _, err := a.createJoinLeavePost(post, channel); err != nil {
 		return model.NewAppError("postJoinChannelMessage", "api.channel.post_user_add_remove_message_and_forget.error", nil, err.Error(), http.StatusInternalServerError)
 	}
 
@@ -1029,15 +1335,15 @@ func (a *App) postJoinChannelMessage(user *model.User, channel *model.Channel) *
 func (a *App) postJoinTeamMessage(user *model.User, channel *model.Channel) *model.AppError {
 	post := &model.Post{
 		ChannelId: channel.Id,
-		Message:   fmt.Sprintf(utils.T("api.team.join_team.post_and_forget"), user.Username),
 		Type:      model.POST_JOIN_TEAM,
 		UserId:    user.Id,
 		Props: model.StringInterface{
 			"username": user.Username,
 		},
 	}
+	post.Message = RenderSystemMessage(post, utils.T)
 
-	if _, err := a.CreatePost(post, channel, false); err != nil {
+	if _, err := a.createJoinLeavePost(post, channel); err != nil {
 		return model.NewAppError("postJoinTeamMessage", "api.channel.post_user_add_remove_message_and_forget.error", nil, err.Error(), http.StatusInternalServerError)
 	}
 
@@ -1089,15 +1395,15 @@ func (a *App) LeaveChannel(channelId string, userId string) *model.AppError {
 func (a *App) postLeaveChannelMessage(user *model.User, channel *model.Channel) *model.AppError {
 	post := &model.Post{
 		ChannelId: channel.Id,
-		Message:   fmt.Sprintf(utils.T("api.channel.leave.left"), user.Username),
 		Type:      model.POST_LEAVE_CHANNEL,
 		UserId:    user.Id,
 		Props: model.StringInterface{
 			"username": user.Username,
 		},
 	}
+	post.Message = RenderSystemMessage(post, utils.T)
 
-	if _, err := a.CreatePost(post, channel, false); err != nil {
+	if _, err := a.createJoinLeavePost(post, channel); err != nil {
 		return model.NewAppError("postLeaveChannelMessage", "api.channel.post_user_add_remove_message_and_forget.error", nil, err.Error(), http.StatusInternalServerError)
 	}
 
@@ -1107,7 +1413,6 @@ func (a *App) postLeaveChannelMessage(user *model.User, channel *model.Channel)
 func (a *App) PostAddToChannelMessage(user *model.User, addedUser *model.User, channel *model.Channel, postRootId string) *model.AppError {
 	post := &model.Post{
 		ChannelId: channel.Id,
-		Message:   fmt.Sprintf(utils.T("api.channel.add_member.added"), addedUser.Username, user.Username),
 		Type:      model.POST_ADD_TO_CHANNEL,
 		UserId:    user.Id,
 		RootId:    postRootId,
@@ -1118,8 +1423,9 @@ func (a *App) PostAddToChannelMessage(user *model.User, addedUser *model.User, c
 			"addedUsername":                addedUser.Username,
 		},
 	}
+	post.Message = RenderSystemMessage(post, utils.T)
 
-	if _, err := a.CreatePost(post, channel, false); err != nil {
+	if _, err := a.createJoinLeavePost(post, channel); err != nil {
 		return model.NewAppError("postAddToChannelMessage", "api.channel.post_user_add_remove_message_and_forget.error", nil, err.Error(), http.StatusInternalServerError)
 	}
 
@@ -1129,7 +1435,6 @@ func (a *App) PostAddToChannelMessage(user *model.User, addedUser *model.User, c
 func (a *App) postAddToTeamMessage(user *model.User, addedUser *model.User, channel *model.Channel, postRootId string) *model.AppError {
 	post := &model.Post{
 		ChannelId: channel.Id,
-		Message:   fmt.Sprintf(utils.T("api.team.add_user_to_team.added"), addedUser.Username, user.Username),
 		Type:      model.POST_ADD_TO_TEAM,
 		UserId:    user.Id,
 		RootId:    postRootId,
@@ -1140,8 +1445,9 @@ func (a *App) postAddToTeamMessage(user *model.User, addedUser *model.User, chan
 			"addedUsername":                addedUser.Username,
 		},
 	}
+	post.Message = RenderSystemMessage(post, utils.T)
 
-	if _, err := a.CreatePost(post, channel, false); err != nil {
+	if _, err := a.createJoinLeavePost(post, channel); err != nil {
 		return model.NewAppError("postAddToTeamMessage", "api.channel.post_user_add_remove_message_and_forget.error", nil, err.Error(), http.StatusInternalServerError)
 	}
 
@@ -1151,7 +1457,6 @@ func (a *App) postAddToTeamMessage(user *model.User, addedUser *model.User, chan
 func (a *App) postRemoveFromChannelMessage(removerUserId string, removedUser *model.User, channel *model.Channel) *model.AppError {
 	post := &model.Post{
 		ChannelId: channel.Id,
-		Message:   fmt.Sprintf(utils.T("api.channel.remove_member.removed"), removedUser.Username),
 		Type:      model.POST_REMOVE_FROM_CHANNEL,
 		UserId:    removerUserId,
 		Props: model.StringInterface{
@@ -1159,8 +1464,9 @@ func (a *App) postRemoveFromChannelMessage(removerUserId string, removedUser *mo
 			"removedUsername": removedUser.Username,
 		},
 	}
+	post.Message = RenderSystemMessage(post, utils.T)
 
-	if _, err := a.CreatePost(post, channel, false); err != nil {
+	if _, err := a.createJoinLeavePost(post, channel); err != nil {
 		return model.NewAppError("postRemoveFromChannelMessage", "api.channel.post_user_add_remove_message_and_forget.error", nil, err.Error(), http.StatusInternalServerError)
 	}
 
@@ -1180,9 +1486,21 @@ func (a *App) removeUserFromChannel(userIdToRemove string, removerUserId string,
 	if cmresult := <-a.Srv.Store.Channel().RemoveMember(channel.Id, userIdToRemove); cmresult.Err != nil {
 		return cmresult.Err
 	}
-	if cmhResult := <-a.Srv.Store.ChannelMemberHistory().LogLeaveEvent(userIdToRemove, channel.Id, model.GetMillis()); cmhResult.Err != nil {
+	actorId, reason := removerUserId, model.CHANNEL_MEMBER_LEAVE_REASON_REMOVED
+	if removerUserId == "" {
+		actorId = model.CHANNEL_MEMBER_HISTORY_ACTOR_SYSTEM
+	} else if removerUserId == userIdToRemove {
+		reason = model.CHANNEL_MEMBER_LEAVE_REASON_LEFT
+	}
+	if cmhResult := <-a.Srv.Store.ChannelMemberHistory().LogLeaveEvent(userIdToRemove, channel.Id, model.GetMillis(), actorId, reason); cmhResult.Err != nil {
 		return cmhResult.Err
 	}
+	if result := <-a.Srv.Store.Channel().RemoveChannelFromSidebarCategories(userIdToRemove, channel.Id); result.Err != nil {
+		return result.Err
+	}
+	if result := <-a.Srv.Store.Thread().DeleteMembershipsForUserInChannel(userIdToRemove, channel.Id); result.Err != nil {
+		return result.Err
+	}
 
 	a.InvalidateCacheForUser(userIdToRemove)
 	a.InvalidateCacheForChannelMembers(channel.Id)
@@ -1258,15 +1576,13 @@ func (a *App) SetActiveChannel(userId string, channelId string) *model.AppError
 }
 
 func (a *App) UpdateChannelLastViewedAt(channelIds []string, userId string) *model.AppError {
-	if result := <-a.Srv.Store.Channel().UpdateLastViewedAt(channelIds, userId); result.Err != nil {
-		return result.Err
+	if _, err := a.updateLastViewedAt(channelIds, userId); err != nil {
+		return err
 	}
 
 	if *a.Config().ServiceSettings.EnableChannelViewedMessages {
 		for _, channelId := range channelIds {
-			message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_CHANNEL_VIEWED, "", "", userId, nil)
-			message.Add("channel_id", channelId)
-			a.Publish(message)
+			a.publishChannelViewed(channelId, userId)
 		}
 	}
 
@@ -1289,6 +1605,15 @@ func (a *App) SearchChannels(teamId string, term string) (*model.ChannelList, *m
 	}
 }
 
+// SearchChannelsForUser searches only the channels of a team that the user is a member of.
+func (a *App) SearchChannelsForUser(userId string, teamId string, term string) (*model.ChannelList, *model.AppError) {
+	if result := <-a.Srv.Store.Channel().SearchForUserInTeam(userId, teamId, term); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return result.Data.(*model.ChannelList), nil
+	}
+}
+
 func (a *App) SearchChannelsUserNotIn(teamId string, userId string, term string) (*model.ChannelList, *model.AppError) {
 	if result := <-a.Srv.Store.Channel().SearchMore(userId, teamId, term); result.Err != nil {
 		return nil, result.Err
@@ -1313,6 +1638,7 @@ func (a *App) ViewChannel(view *model.ChannelView, userId string, clearPushNotif
 		channelIds = append(channelIds, view.PrevChannelId)
 
 		if *a.Config().EmailSettings.SendPushNotifications && clearPushNotifications && len(view.ChannelId) > 0 {
+			a.flushChannelViewsForUser(userId)
 			pchan = a.Srv.Store.User().GetUnreadCountForChannel(userId, view.ChannelId)
 		}
 	}
@@ -1321,8 +1647,6 @@ func (a *App) ViewChannel(view *model.ChannelView, userId string, clearPushNotif
 		return map[string]int64{}, nil
 	}
 
-	uchan := a.Srv.Store.Channel().UpdateLastViewedAt(channelIds, userId)
-
 	if pchan != nil {
 		if result := <-pchan; result.Err != nil {
 			return nil, result.Err
@@ -1333,44 +1657,121 @@ func (a *App) ViewChannel(view *model.ChannelView, userId string, clearPushNotif
 		}
 	}
 
-	var times map[string]int64
-	if result := <-uchan; result.Err != nil {
-		return nil, result.Err
-	} else {
-		times = result.Data.(map[string]int64)
+	times, err := a.updateLastViewedAt(channelIds, userId)
+	if err != nil {
+		return nil, err
 	}
 
 	if *a.Config().ServiceSettings.EnableChannelViewedMessages && model.IsValidId(view.ChannelId) {
-		message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_CHANNEL_VIEWED, "", "", userId, nil)
-		message.Add("channel_id", view.ChannelId)
-		a.Publish(message)
+		a.publishChannelViewed(view.ChannelId, userId)
 	}
 
 	return times, nil
 }
 
+// MarkChannelsAsViewed marks every channel that the user is a member of in the team, or in every team if teamId is
+// empty, as read, returning the ids of the channels that had unread posts. The last read posts in those channels are
+// moved to their latest posts. Threads are marked as read too for users who have collapsed threads turned on, since
+// their mentions in threads aren't counted in the channels.
+func (a *App) MarkChannelsAsViewed(userId string, teamId string, clearPushNotifications bool) ([]string, *model.AppError) {
+	// Any views that are waiting to be written would otherwise make channels look unread
+	a.flushChannelViewsForUser(userId)
+
+	result := <-a.Srv.Store.Channel().GetChannelUnreadsForUser(teamId, userId)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	channelIds := []string{}
+	var mentionedChannelIds []string
+	for _, unread := range result.Data.([]*model.ChannelUnread) {
+		if unread.MsgCount > 0 || unread.MentionCount > 0 {
+			channelIds = append(channelIds, unread.ChannelId)
+		}
+
+		if unread.MentionCount > 0 {
+			mentionedChannelIds = append(mentionedChannelIds, unread.ChannelId)
+		}
+	}
+
+	lastReadPostIds := map[string]string{}
+	if len(channelIds) > 0 {
+		if _, err := a.updateLastViewedAt(channelIds, userId); err != nil {
+			return nil, err
+		}
+
+		result := <-a.Srv.Store.Channel().UpdateLastReadPostsToLatest(channelIds, userId)
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		lastReadPostIds = result.Data.(map[string]string)
+	}
+
+	if a.collapsedThreadsEnabled(userId) {
+		if result := <-a.Srv.Store.Thread().MarkAllAsReadForUser(userId, teamId, model.GetMillis()); result.Err != nil {
+			return nil, result.Err
+		}
+	}
+
+	if *a.Config().EmailSettings.SendPushNotifications && clearPushNotifications {
+		for _, channelId := range mentionedChannelIds {
+			a.ClearPushNotification(userId, channelId)
+		}
+	}
+
+	if *a.Config().ServiceSettings.EnableChannelViewedMessages && len(channelIds) > 0 {
+		message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_MULTIPLE_CHANNELS_VIEWED, "", "", userId, nil)
+		message.Add("team_id", teamId)
+		message.Add("channel_ids", model.ArrayToJson(channelIds))
+		message.Add("last_read_post_ids", model.MapToJson(lastReadPostIds))
+		a.Publish(message)
+	}
+
+	return channelIds, nil
+}
+
 func (a *App) PermanentDeleteChannel(channel *model.Channel) *model.AppError {
-	if result := <-a.Srv.Store.Post().PermanentDeleteByChannel(channel.Id); result.Err != nil {
-		return result.Err
+	_, err := a.permanentDeleteChannel(channel)
+	return err
+}
+
+// permanentDeleteChannel deletes the channel and everything in it, returning how many files were deleted. The files
+// go first since some of them are only found through the channel's posts.
+func (a *App) permanentDeleteChannel(channel *model.Channel) (int64, *model.AppError) {
+	filesDeleted, err := a.permanentDeleteChannelFiles(channel)
+	if err != nil {
+		return filesDeleted, err
+	}
+
+	// Posts are deleted in batches so that channels with a lot of them don't hold up the database
+	for {
+		result := <-a.Srv.Store.Post().PermanentDeleteByChannelBatch(channel.Id, CHANNEL_DELETION_POST_BATCH_SIZE)
+		if result.Err != nil {
+			return filesDeleted, result.Err
+		}
+
+		if result.Data.(int64) == 0 {
+			break
+		}
 	}
 
 	if result := <-a.Srv.Store.Channel().PermanentDeleteMembersByChannel(channel.Id); result.Err != nil {
-		return result.Err
+		return filesDeleted, result.Err
 	}
 
 	if result := <-a.Srv.Store.Webhook().PermanentDeleteIncomingByChannel(channel.Id); result.Err != nil {
-		return result.Err
+		return filesDeleted, result.Err
 	}
 
 	if result := <-a.Srv.Store.Webhook().PermanentDeleteOutgoingByChannel(channel.Id); result.Err != nil {
-		return result.Err
+		return filesDeleted, result.Err
 	}
 
 	if result := <-a.Srv.Store.Channel().PermanentDelete(channel.Id); result.Err != nil {
-		return result.Err
+		return filesDeleted, result.Err
 	}
 
-	return nil
+	return filesDeleted, nil
 }
 
 // This function is intended for use from the CLI. It is not robust against people joining the channel while the move
@@ -1414,13 +1815,14 @@ func (a *App) postChannelMoveMessage(user *model.User, channel *model.Channel, p
 
 	post := &model.Post{
 		ChannelId: channel.Id,
-		Message:   fmt.Sprintf(utils.T("api.team.move_channel.success"), previousTeam.Name),
 		Type:      model.POST_MOVE_CHANNEL,
 		UserId:    user.Id,
 		Props: model.StringInterface{
-			"username": user.Username,
+			"username":           user.Username,
+			"previous_team_name": previousTeam.Name,
 		},
 	}
+	post.Message = RenderSystemMessage(post, utils.T)
 
 	if _, err := a.CreatePost(post, channel, false); err != nil {
 		return model.NewAppError("postChannelMoveMessage", "api.team.move_channel.post.error", nil, err.Error(), http.StatusInternalServerError)
@@ -1440,6 +1842,10 @@ func (a *App) GetPinnedPosts(channelId string) (*model.PostList, *model.AppError
 func (a *App) GetDirectChannel(userId1, userId2 string) (*model.Channel, *model.AppError) {
 	result := <-a.Srv.Store.Channel().GetByName("", model.GetDMNameFromIds(userId1, userId2), true)
 	if result.Err != nil && result.Err.Id == store.MISSING_CHANNEL_ERROR {
+		if err := a.checkCanCreateDirectChannel(userId1, userId2); err != nil {
+			return nil, err
+		}
+
 		result := <-a.Srv.Store.Channel().CreateDirectChannel(userId1, userId2)
 		if result.Err != nil {
 			return nil, model.NewAppError("GetOrCreateDMChannel", "web.incoming_webhook.channel.app_error", nil, "err="+result.Err.Message, http.StatusBadRequest)
@@ -1448,10 +1854,10 @@ func (a *App) GetDirectChannel(userId1, userId2 string) (*model.Channel, *model.
 		a.InvalidateCacheForUser(userId2)
 
 		channel := result.Data.(*model.Channel)
-		if result := <-a.Srv.Store.ChannelMemberHistory().LogJoinEvent(userId1, channel.Id, model.GetMillis()); result.Err != nil {
+		if result := <-a.Srv.Store.ChannelMemberHistory().LogJoinEvent(userId1, channel.Id, model.GetMillis(), userId1); result.Err != nil {
 			mlog.Warn(fmt.Sprintf("Failed to update ChannelMemberHistory table %v", result.Err))
 		}
-		if result := <-a.Srv.Store.ChannelMemberHistory().LogJoinEvent(userId2, channel.Id, model.GetMillis()); result.Err != nil {
+		if result := <-a.Srv.Store.ChannelMemberHistory().LogJoinEvent(userId2, channel.Id, model.GetMillis(), userId1); result.Err != nil {
 			mlog.Warn(fmt.Sprintf("Failed to update ChannelMemberHistory table %v", result.Err))
 		}
 
@@ -1463,6 +1869,8 @@ func (a *App) GetDirectChannel(userId1, userId2 string) (*model.Channel, *model.
 }
 
 func (a *App) ToggleMuteChannel(channelId string, userId string) *model.ChannelMember {
+	a.flushChannelViewsForUser(userId)
+
 	result := <-a.Srv.Store.Channel().GetMember(channelId, userId)
 
 	if result.Err != nil {
@@ -0,0 +1,286 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+	"github.com/nicksnyder/go-i18n/i18n"
+)
+
+const (
+	CHANNEL_ARCHIVING_BATCH_SIZE = 1000
+
+	// Only this many of the warned and archived channels are listed in the summary sent to system admins so that it
+	// stays short enough to post.
+	CHANNEL_ARCHIVING_SUMMARY_MAX_CHANNELS = 50
+)
+
+// channelArchivingExemptions caches what's needed to check whether channels are exempt while a run goes through them.
+type channelArchivingExemptions struct {
+	teamNames       map[string]bool
+	namePattern     *regexp.Regexp
+	teams           map[string]*model.Team
+	defaultChannels map[string]map[string]bool
+}
+
+func (a *App) isExemptFromChannelArchiving(channel *model.Channel, exemptions *channelArchivingExemptions) (bool, *model.AppError) {
+	if channel.Name == model.DEFAULT_CHANNEL || channel.Name == "off-topic" {
+		return true, nil
+	}
+
+	if exemptions.namePattern != nil && exemptions.namePattern.MatchString(channel.Name) {
+		return true, nil
+	}
+
+	team, ok := exemptions.teams[channel.TeamId]
+	if !ok {
+		var err *model.AppError
+		if team, err = a.GetTeam(channel.TeamId); err != nil {
+			return false, err
+		}
+		exemptions.teams[channel.TeamId] = team
+	}
+
+	if exemptions.teamNames[team.Name] {
+		return true, nil
+	}
+
+	defaultChannels, ok := exemptions.defaultChannels[channel.TeamId]
+	if !ok {
+		channelIds, err := a.GetTeamDefaultChannels(channel.TeamId)
+		if err != nil {
+			return false, err
+		}
+
+		defaultChannels = make(map[string]bool, len(channelIds))
+		for _, channelId := range channelIds {
+			defaultChannels[channelId] = true
+		}
+		exemptions.defaultChannels[channel.TeamId] = defaultChannels
+	}
+
+	if defaultChannels[channel.Id] {
+		return true, nil
+	}
+
+	return a.hasKeepMarker(channel.Id)
+}
+
+// hasKeepMarker returns whether any of the channel's pinned posts have the hashtag that stops it from being archived.
+func (a *App) hasKeepMarker(channelId string) (bool, *model.AppError) {
+	result := <-a.Srv.Store.Channel().GetPinnedPosts(channelId)
+	if result.Err != nil {
+		return false, result.Err
+	}
+
+	for _, post := range result.Data.(*model.PostList).Posts {
+		hashtags, _ := model.ParseHashtags(post.Message)
+		for _, hashtag := range strings.Fields(hashtags) {
+			if strings.EqualFold(hashtag, model.CHANNEL_ARCHIVING_KEEP_HASHTAG) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// postChannelInactiveWarning posts the warning that the channel will be archived. The warning is made at the time of
+// the run since the grace period is counted from it.
+func (a *App) postChannelInactiveWarning(channel *model.Channel, bot *model.User, now int64, inactiveDays int, warningDays int) *model.AppError {
+	post := &model.Post{
+		ChannelId: channel.Id,
+		UserId:    bot.Id,
+		CreateAt:  now,
+		Type:      model.POST_CHANNEL_INACTIVE,
+		Props: model.StringInterface{
+			"inactive_days": strconv.Itoa(inactiveDays),
+			"warning_days":  strconv.Itoa(warningDays),
+		},
+	}
+	post.Message = RenderSystemMessage(post, utils.T)
+
+	if _, err := a.CreatePost(post, channel, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ArchiveInactiveChannels finds the public channels that haven't had a post from anyone for the configured number of
+// days, not counting system messages. Their members are warned with a system message when the channel is within
+// WarningDays of being archived, and channels are archived once they've been inactive for InactiveDays and at least
+// WarningDays have passed since the warning, so a channel is never archived without warning. Posting in a channel
+// restarts its inactivity. Town Square, Off-Topic, the team's default channels, exempt teams and channel names, and
+// channels with a pinned #keep post are exempt. System admins are sent a summary of what was done. If dryRun is set,
+// nothing is changed and the result and the summary hold what would have been.
+func (a *App) ArchiveInactiveChannels(now int64, dryRun bool) (*model.ChannelArchivingResult, *model.AppError) {
+	settings := a.Config().ChannelArchivingSettings
+	day := int64(24 * time.Hour / time.Millisecond)
+	warnSince := now - int64(*settings.InactiveDays-*settings.WarningDays)*day
+	gracePeriod := int64(*settings.WarningDays) * day
+
+	result := &model.ChannelArchivingResult{
+		DryRun:             dryRun,
+		InactiveSince:      now - int64(*settings.InactiveDays)*day,
+		WarnedChannelIds:   []string{},
+		ArchivedChannelIds: []string{},
+	}
+
+	exemptions := &channelArchivingExemptions{
+		teamNames:       make(map[string]bool),
+		teams:           make(map[string]*model.Team),
+		defaultChannels: make(map[string]map[string]bool),
+	}
+	for _, name := range strings.Fields(*settings.ExemptTeams) {
+		exemptions.teamNames[name] = true
+	}
+	if *settings.ExemptChannelNamePattern != "" {
+		pattern, err := regexp.Compile(*settings.ExemptChannelNamePattern)
+		if err != nil {
+			return nil, model.NewAppError("ArchiveInactiveChannels", "model.config.is_valid.channel_archiving.exempt_channel_name_pattern.app_error", nil, err.Error(), http.StatusInternalServerError)
+		}
+		exemptions.namePattern = pattern
+	}
+
+	bot, err := a.EnsureSystemBot()
+	if err != nil {
+		return nil, err
+	}
+
+	var warned, archived []*model.Channel
+	afterChannelId := ""
+	for {
+		storeResult := <-a.Srv.Store.Channel().GetInactivePublicChannels(warnSince, afterChannelId, CHANNEL_ARCHIVING_BATCH_SIZE)
+		if storeResult.Err != nil {
+			return nil, storeResult.Err
+		}
+		batch := storeResult.Data.([]*model.ChannelActivity)
+
+		for _, activity := range batch {
+			channel, err := a.GetChannel(activity.ChannelId)
+			if err != nil {
+				return nil, err
+			}
+
+			if exempt, err := a.isExemptFromChannelArchiving(channel, exemptions); err != nil {
+				return nil, err
+			} else if exempt {
+				result.ExemptCount++
+				continue
+			}
+
+			if activity.WarnedAt <= activity.LastActivityAt {
+				if !dryRun {
+					inactiveDays := int((now - activity.LastActivityAt) / day)
+					if err := a.postChannelInactiveWarning(channel, bot, now, inactiveDays, *settings.WarningDays); err != nil {
+						// Don't start the grace period until the members have been told about it
+						mlog.Error("Unable to warn the members of an inactive channel", mlog.String("channel_id", channel.Id), mlog.Err(err))
+						continue
+					}
+				}
+
+				result.WarnedChannelIds = append(result.WarnedChannelIds, channel.Id)
+				warned = append(warned, channel)
+			} else if activity.LastActivityAt < result.InactiveSince && activity.WarnedAt+gracePeriod <= now {
+				if !dryRun {
+					if err := a.DeleteChannel(channel, bot.Id); err != nil {
+						mlog.Error("Unable to archive inactive channel", mlog.String("channel_id", channel.Id), mlog.Err(err))
+						continue
+					}
+				}
+
+				result.ArchivedChannelIds = append(result.ArchivedChannelIds, channel.Id)
+				archived = append(archived, channel)
+			}
+		}
+
+		if len(batch) < CHANNEL_ARCHIVING_BATCH_SIZE {
+			break
+		}
+		afterChannelId = batch[len(batch)-1].ChannelId
+	}
+
+	if len(warned) > 0 || len(archived) > 0 {
+		a.sendChannelArchivingSummary(bot, result, warned, archived, exemptions.teams)
+	}
+
+	return result, nil
+}
+
+// sendChannelArchivingSummary sends each system admin a direct message from the system bot listing the channels that
+// were warned and archived.
+func (a *App) sendChannelArchivingSummary(bot *model.User, result *model.ChannelArchivingResult, warned []*model.Channel, archived []*model.Channel, teams map[string]*model.Team) {
+	storeResult := <-a.Srv.Store.User().GetSystemAdminProfiles()
+	if storeResult.Err != nil {
+		mlog.Error("Unable to get the system admins to send the channel archiving summary to", mlog.Err(storeResult.Err))
+		return
+	}
+
+	for _, admin := range storeResult.Data.(map[string]*model.User) {
+		if admin.DeleteAt != 0 || admin.IsBot {
+			continue
+		}
+
+		channel, err := a.GetDirectChannel(bot.Id, admin.Id)
+		if err != nil {
+			mlog.Error("Unable to send the channel archiving summary", mlog.String("user_id", admin.Id), mlog.Err(err))
+			continue
+		}
+
+		post := &model.Post{
+			ChannelId: channel.Id,
+			UserId:    bot.Id,
+			Message:   channelArchivingSummaryMessage(utils.GetUserTranslations(admin.Locale), result, warned, archived, teams),
+		}
+
+		if _, err := a.CreatePost(post, channel, false); err != nil {
+			mlog.Error("Unable to send the channel archiving summary", mlog.String("user_id", admin.Id), mlog.Err(err))
+		}
+	}
+}
+
+func channelArchivingSummaryMessage(T i18n.TranslateFunc, result *model.ChannelArchivingResult, warned []*model.Channel, archived []*model.Channel, teams map[string]*model.Team) string {
+	counts := map[string]interface{}{"Warned": len(warned), "Archived": len(archived)}
+
+	var lines []string
+	if result.DryRun {
+		lines = append(lines, T("app.channel_archiving.summary.dry_run", counts))
+	} else {
+		lines = append(lines, T("app.channel_archiving.summary", counts))
+	}
+
+	listChannels := func(heading string, channels []*model.Channel) {
+		if len(channels) == 0 {
+			return
+		}
+
+		lines = append(lines, "", heading)
+		for i, channel := range channels {
+			if i == CHANNEL_ARCHIVING_SUMMARY_MAX_CHANNELS {
+				lines = append(lines, T("app.channel_archiving.summary.more", map[string]interface{}{"Count": len(channels) - i}))
+				break
+			}
+
+			teamName := ""
+			if team := teams[channel.TeamId]; team != nil {
+				teamName = team.Name
+			}
+			lines = append(lines, "- "+channel.DisplayName+" ("+teamName+"/"+channel.Name+")")
+		}
+	}
+
+	listChannels(T("app.channel_archiving.summary.warned"), warned)
+	listChannels(T("app.channel_archiving.summary.archived"), archived)
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestArchiveInactiveChannels(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.ChannelArchivingSettings.InactiveDays = 30
+		*cfg.ChannelArchivingSettings.WarningDays = 7
+	})
+
+	day := int64(24 * 60 * 60 * 1000)
+	now := model.GetMillis() + 31*day
+
+	post := func(channel *model.Channel, message string, createAt int64, pinned bool) {
+		_, err := th.App.CreatePost(&model.Post{
+			UserId:    th.BasicUser.Id,
+			ChannelId: channel.Id,
+			Message:   message,
+			CreateAt:  createAt,
+			IsPinned:  pinned,
+		}, channel, false)
+		require.Nil(t, err)
+	}
+
+	inactive := th.CreateChannel(th.BasicTeam)
+	post(inactive, "hello", 0, false)
+
+	revived := th.CreateChannel(th.BasicTeam)
+
+	active := th.CreateChannel(th.BasicTeam)
+	post(active, "still here", now-5*day, false)
+
+	kept := th.CreateChannel(th.BasicTeam)
+	post(kept, "please #keep this channel", 0, true)
+
+	townSquare, err := th.App.GetChannelByName(model.DEFAULT_CHANNEL, th.BasicTeam.Id)
+	require.Nil(t, err)
+
+	result, err := th.App.ArchiveInactiveChannels(now, true)
+	require.Nil(t, err)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, now-30*day, result.InactiveSince)
+	assert.Contains(t, result.WarnedChannelIds, inactive.Id)
+	assert.Contains(t, result.WarnedChannelIds, revived.Id)
+	assert.NotContains(t, result.WarnedChannelIds, active.Id)
+	assert.NotContains(t, result.WarnedChannelIds, kept.Id)
+	assert.NotContains(t, result.WarnedChannelIds, townSquare.Id)
+
+	getWarnedAt := func(channel *model.Channel) int64 {
+		posts, err := th.App.GetPostsPage(channel.Id, 0, 60)
+		require.Nil(t, err)
+
+		var warnedAt int64
+		for _, post := range posts.Posts {
+			if post.Type == model.POST_CHANNEL_INACTIVE && post.CreateAt > warnedAt {
+				warnedAt = post.CreateAt
+			}
+		}
+		return warnedAt
+	}
+
+	assert.Equal(t, int64(0), getWarnedAt(inactive), "a dry run shouldn't warn anyone")
+
+	result, err = th.App.ArchiveInactiveChannels(now, false)
+	require.Nil(t, err)
+	assert.False(t, result.DryRun)
+	assert.Contains(t, result.WarnedChannelIds, inactive.Id)
+	assert.Contains(t, result.WarnedChannelIds, revived.Id)
+	assert.NotContains(t, result.ArchivedChannelIds, inactive.Id)
+	assert.Equal(t, now, getWarnedAt(inactive))
+
+	// The warning doesn't count as activity, so the channel is only warned once
+	result, err = th.App.ArchiveInactiveChannels(now+day, false)
+	require.Nil(t, err)
+	assert.NotContains(t, result.WarnedChannelIds, inactive.Id)
+
+	post(revived, "not dead yet", now+day, false)
+
+	// Nothing is archived until the grace period is over
+	result, err = th.App.ArchiveInactiveChannels(now+6*day, false)
+	require.Nil(t, err)
+	assert.NotContains(t, result.ArchivedChannelIds, inactive.Id)
+
+	result, err = th.App.ArchiveInactiveChannels(now+7*day, false)
+	require.Nil(t, err)
+	assert.Contains(t, result.ArchivedChannelIds, inactive.Id)
+	assert.NotContains(t, result.ArchivedChannelIds, revived.Id)
+	assert.NotContains(t, result.ArchivedChannelIds, kept.Id)
+	assert.NotContains(t, result.ArchivedChannelIds, townSquare.Id)
+
+	channel, err := th.App.GetChannel(inactive.Id)
+	require.Nil(t, err)
+	assert.NotEqual(t, int64(0), channel.DeleteAt)
+
+	channel, err = th.App.GetChannel(revived.Id)
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), channel.DeleteAt)
+
+	bot, err := th.App.EnsureSystemBot()
+	require.Nil(t, err)
+	dm, err := th.App.GetDirectChannel(bot.Id, th.SystemAdminUser.Id)
+	require.Nil(t, err)
+	posts, err := th.App.GetPostsPage(dm.Id, 0, 60)
+	require.Nil(t, err)
+
+	summarized := false
+	for _, post := range posts.Posts {
+		if strings.Contains(post.Message, "Archived:") && strings.Contains(post.Message, inactive.Name) {
+			summarized = true
+		}
+	}
+	assert.True(t, summarized, "system admins should be sent a summary of the archived channels")
+
+	t.Run("exempt teams and channel names are configurable", func(t *testing.T) {
+		byName := th.CreateChannel(th.BasicTeam)
+		otherTeam := th.CreateTeam()
+		byTeam := th.CreateChannel(otherTeam)
+
+		th.App.UpdateConfig(func(cfg *model.Config) {
+			*cfg.ChannelArchivingSettings.ExemptTeams = otherTeam.Name
+			*cfg.ChannelArchivingSettings.ExemptChannelNamePattern = "^" + byName.Name + "$"
+		})
+		defer th.App.UpdateConfig(func(cfg *model.Config) {
+			*cfg.ChannelArchivingSettings.ExemptTeams = ""
+			*cfg.ChannelArchivingSettings.ExemptChannelNamePattern = ""
+		})
+
+		result, err := th.App.ArchiveInactiveChannels(now, true)
+		require.Nil(t, err)
+		assert.NotContains(t, result.WarnedChannelIds, byName.Id)
+		assert.NotContains(t, result.WarnedChannelIds, byTeam.Id)
+	})
+}
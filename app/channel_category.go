@@ -0,0 +1,304 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// GetSidebarCategories returns the user's sidebar categories for the given team in the order that
+// they should be displayed. The default categories are created the first time this is called, and
+// any channels that the user hasn't placed into a category are added to the Channels or Direct
+// Messages categories as appropriate.
+func (a *App) GetSidebarCategories(userId string, teamId string) (*model.OrderedSidebarCategories, *model.AppError) {
+	categories, err := a.getStoredSidebarCategories(userId, teamId)
+	if err != nil {
+		return nil, err
+	}
+
+	channels, err := a.getChannelsForSidebar(userId, teamId)
+	if err != nil {
+		return nil, err
+	}
+
+	addImplicitChannelsToSidebarCategories(categories, channels)
+
+	return categories, nil
+}
+
+func (a *App) GetSidebarCategoryOrder(userId string, teamId string) ([]string, *model.AppError) {
+	categories, err := a.getStoredSidebarCategories(userId, teamId)
+	if err != nil {
+		return nil, err
+	}
+
+	return categories.Order, nil
+}
+
+func (a *App) GetSidebarCategory(userId string, teamId string, categoryId string) (*model.SidebarCategoryWithChannels, *model.AppError) {
+	categories, err := a.GetSidebarCategories(userId, teamId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, category := range categories.Categories {
+		if category.Id == categoryId {
+			return category, nil
+		}
+	}
+
+	return nil, model.NewAppError("GetSidebarCategory", "app.channel.sidebar_categories.not_found.app_error", nil, "category_id="+categoryId, http.StatusNotFound)
+}
+
+func (a *App) CreateSidebarCategory(userId string, teamId string, category *model.SidebarCategoryWithChannels) (*model.SidebarCategoryWithChannels, *model.AppError) {
+	// Make sure that the default categories exist first so that the new one is sorted after them
+	if _, err := a.getStoredSidebarCategories(userId, teamId); err != nil {
+		return nil, err
+	}
+
+	if err := a.validateSidebarChannels(userId, teamId, []*model.SidebarCategoryWithChannels{category}); err != nil {
+		return nil, err
+	}
+
+	category.Id = ""
+	category.UserId = userId
+	category.TeamId = teamId
+	category.Type = model.SIDEBAR_CATEGORY_CUSTOM
+	if category.Channels == nil {
+		category.Channels = []string{}
+	}
+
+	result := <-a.Srv.Store.Channel().CreateSidebarCategory(category)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	created := result.Data.(*model.SidebarCategoryWithChannels)
+
+	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_SIDEBAR_CATEGORY_CREATED, "", "", userId, nil)
+	message.Add("team_id", teamId)
+	message.Add("category_id", created.Id)
+	a.Publish(message)
+
+	return created, nil
+}
+
+// UpdateSidebarCategories changes the names of the given categories and the channels in them. A
+// channel can only be in a single category, so any channel added to one of these categories will be
+// removed from whatever category it was in before.
+func (a *App) UpdateSidebarCategories(userId string, teamId string, categories []*model.SidebarCategoryWithChannels) ([]*model.SidebarCategoryWithChannels, *model.AppError) {
+	stored, err := a.getStoredSidebarCategories(userId, teamId)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]*model.SidebarCategoryWithChannels, len(stored.Categories))
+	for _, category := range stored.Categories {
+		existing[category.Id] = category
+	}
+
+	for _, category := range categories {
+		original, ok := existing[category.Id]
+		if !ok {
+			return nil, model.NewAppError("UpdateSidebarCategories", "app.channel.sidebar_categories.not_found.app_error", nil, "category_id="+category.Id, http.StatusNotFound)
+		}
+
+		if original.Type != model.SIDEBAR_CATEGORY_CUSTOM && category.DisplayName != original.DisplayName {
+			return nil, model.NewAppError("UpdateSidebarCategories", "app.channel.sidebar_categories.rename_default.app_error", nil, "category_id="+category.Id, http.StatusBadRequest)
+		}
+	}
+
+	if err := a.validateSidebarChannels(userId, teamId, categories); err != nil {
+		return nil, err
+	}
+
+	result := <-a.Srv.Store.Channel().UpdateSidebarCategories(userId, teamId, categories)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	updated := result.Data.([]*model.SidebarCategoryWithChannels)
+
+	updatedIds := make([]string, len(updated))
+	for i, category := range updated {
+		updatedIds[i] = category.Id
+	}
+
+	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_SIDEBAR_CATEGORY_UPDATED, "", "", userId, nil)
+	message.Add("team_id", teamId)
+	message.Add("category_ids", model.ArrayToJson(updatedIds))
+	a.Publish(message)
+
+	return updated, nil
+}
+
+// UpdateSidebarCategoryOrder changes the order of the user's categories. The new order must contain
+// each of the user's categories for the team exactly once.
+func (a *App) UpdateSidebarCategoryOrder(userId string, teamId string, categoryOrder []string) *model.AppError {
+	stored, err := a.getStoredSidebarCategories(userId, teamId)
+	if err != nil {
+		return err
+	}
+
+	if len(categoryOrder) != len(stored.Order) {
+		return model.NewAppError("UpdateSidebarCategoryOrder", "app.channel.sidebar_categories.order.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	remaining := make(map[string]bool, len(stored.Order))
+	for _, categoryId := range stored.Order {
+		remaining[categoryId] = true
+	}
+
+	for _, categoryId := range categoryOrder {
+		if !remaining[categoryId] {
+			return model.NewAppError("UpdateSidebarCategoryOrder", "app.channel.sidebar_categories.order.app_error", nil, "category_id="+categoryId, http.StatusBadRequest)
+		}
+
+		delete(remaining, categoryId)
+	}
+
+	if result := <-a.Srv.Store.Channel().UpdateSidebarCategoryOrder(userId, teamId, categoryOrder); result.Err != nil {
+		return result.Err
+	}
+
+	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_SIDEBAR_CATEGORY_ORDER_UPDATED, "", "", userId, nil)
+	message.Add("team_id", teamId)
+	message.Add("order", model.ArrayToJson(categoryOrder))
+	a.Publish(message)
+
+	return nil
+}
+
+// DeleteSidebarCategory deletes one of the user's custom categories. Any channels in it are
+// returned to the default categories.
+func (a *App) DeleteSidebarCategory(userId string, teamId string, categoryId string) *model.AppError {
+	result := <-a.Srv.Store.Channel().GetSidebarCategory(categoryId)
+	if result.Err != nil {
+		return result.Err
+	}
+	category := result.Data.(*model.SidebarCategoryWithChannels)
+
+	if category.UserId != userId || category.TeamId != teamId {
+		return model.NewAppError("DeleteSidebarCategory", "app.channel.sidebar_categories.not_found.app_error", nil, "category_id="+categoryId, http.StatusNotFound)
+	}
+
+	if category.Type != model.SIDEBAR_CATEGORY_CUSTOM {
+		return model.NewAppError("DeleteSidebarCategory", "app.channel.sidebar_categories.delete_default.app_error", nil, "category_id="+categoryId, http.StatusBadRequest)
+	}
+
+	if result := <-a.Srv.Store.Channel().DeleteSidebarCategory(categoryId); result.Err != nil {
+		return result.Err
+	}
+
+	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_SIDEBAR_CATEGORY_DELETED, "", "", userId, nil)
+	message.Add("team_id", teamId)
+	message.Add("category_id", categoryId)
+	a.Publish(message)
+
+	return nil
+}
+
+func (a *App) getStoredSidebarCategories(userId string, teamId string) (*model.OrderedSidebarCategories, *model.AppError) {
+	result := <-a.Srv.Store.Channel().GetSidebarCategories(userId, teamId)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	categories := result.Data.(*model.OrderedSidebarCategories)
+	if len(categories.Categories) > 0 {
+		return categories, nil
+	}
+
+	result = <-a.Srv.Store.Channel().CreateInitialSidebarCategories(userId, teamId)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.OrderedSidebarCategories), nil
+}
+
+func (a *App) getChannelsForSidebar(userId string, teamId string) (*model.ChannelList, *model.AppError) {
+	result := <-a.Srv.Store.Channel().GetChannels(teamId, userId)
+	if result.Err != nil {
+		if result.Err.Id == "store.sql_channel.get_channels.not_found.app_error" {
+			return &model.ChannelList{}, nil
+		}
+
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.ChannelList), nil
+}
+
+// validateSidebarChannels checks that every channel being placed into the given categories is one
+// that the user belongs to and that no channel is placed into more than one category.
+func (a *App) validateSidebarChannels(userId string, teamId string, categories []*model.SidebarCategoryWithChannels) *model.AppError {
+	channels, err := a.getChannelsForSidebar(userId, teamId)
+	if err != nil {
+		return err
+	}
+
+	isMember := make(map[string]bool, len(*channels))
+	for _, channel := range *channels {
+		isMember[channel.Id] = true
+	}
+
+	seen := make(map[string]bool)
+	for _, category := range categories {
+		for _, channelId := range category.Channels {
+			if !isMember[channelId] || seen[channelId] {
+				return model.NewAppError("validateSidebarChannels", "app.channel.sidebar_categories.invalid_channel.app_error", nil, "channel_id="+channelId, http.StatusBadRequest)
+			}
+
+			seen[channelId] = true
+		}
+	}
+
+	return nil
+}
+
+// addImplicitChannelsToSidebarCategories removes channels that the user no longer belongs to from the
+// given categories, and then adds any channels that aren't otherwise categorized to the end of the
+// Channels or Direct Messages categories.
+func addImplicitChannelsToSidebarCategories(categories *model.OrderedSidebarCategories, channels *model.ChannelList) {
+	channelsById := make(map[string]*model.Channel, len(*channels))
+	for _, channel := range *channels {
+		channelsById[channel.Id] = channel
+	}
+
+	categorized := make(map[string]bool)
+
+	var channelsCategory, directMessagesCategory *model.SidebarCategoryWithChannels
+	for _, category := range categories.Categories {
+		switch category.Type {
+		case model.SIDEBAR_CATEGORY_CHANNELS:
+			channelsCategory = category
+		case model.SIDEBAR_CATEGORY_DIRECT_MESSAGES:
+			directMessagesCategory = category
+		}
+
+		channelIds := make([]string, 0, len(category.Channels))
+		for _, channelId := range category.Channels {
+			if _, ok := channelsById[channelId]; ok && !categorized[channelId] {
+				channelIds = append(channelIds, channelId)
+				categorized[channelId] = true
+			}
+		}
+		category.Channels = channelIds
+	}
+
+	for _, channel := range *channels {
+		if categorized[channel.Id] {
+			continue
+		}
+
+		if channel.IsGroupOrDirect() {
+			if directMessagesCategory != nil {
+				directMessagesCategory.Channels = append(directMessagesCategory.Channels, channel.Id)
+			}
+		} else if channelsCategory != nil {
+			channelsCategory.Channels = append(channelsCategory.Channels, channel.Id)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestGetSidebarCategoriesMigratesFavorites(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	favorite := th.CreateChannel(th.BasicTeam)
+	notFavorite := th.CreateChannel(th.BasicTeam)
+	dm := th.CreateDmChannel(th.BasicUser2)
+
+	err := th.App.UpdatePreferences(th.BasicUser.Id, model.Preferences{
+		{UserId: th.BasicUser.Id, Category: model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, Name: favorite.Id, Value: "true"},
+		{UserId: th.BasicUser.Id, Category: model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, Name: notFavorite.Id, Value: "false"},
+		{UserId: th.BasicUser.Id, Category: model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, Name: dm.Id, Value: "true"},
+	})
+	require.Nil(t, err)
+
+	categories, err := th.App.GetSidebarCategories(th.BasicUser.Id, th.BasicTeam.Id)
+	require.Nil(t, err)
+	require.Len(t, categories.Categories, 3)
+	require.Equal(t, []string{categories.Categories[0].Id, categories.Categories[1].Id, categories.Categories[2].Id}, categories.Order)
+
+	favorites := categories.Categories[0]
+	channels := categories.Categories[1]
+	directMessages := categories.Categories[2]
+
+	assert.Equal(t, model.SIDEBAR_CATEGORY_FAVORITES, favorites.Type)
+	assert.Equal(t, model.SIDEBAR_CATEGORY_CHANNELS, channels.Type)
+	assert.Equal(t, model.SIDEBAR_CATEGORY_DIRECT_MESSAGES, directMessages.Type)
+
+	assert.ElementsMatch(t, []string{favorite.Id, dm.Id}, favorites.Channels)
+	assert.Contains(t, channels.Channels, notFavorite.Id)
+	assert.Contains(t, channels.Channels, th.BasicChannel.Id)
+	assert.NotContains(t, channels.Channels, favorite.Id)
+	assert.Empty(t, directMessages.Channels)
+
+	t.Run("favorites are only migrated once", func(t *testing.T) {
+		err := th.App.UpdatePreferences(th.BasicUser.Id, model.Preferences{
+			{UserId: th.BasicUser.Id, Category: model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, Name: notFavorite.Id, Value: "true"},
+		})
+		require.Nil(t, err)
+
+		categories, err := th.App.GetSidebarCategories(th.BasicUser.Id, th.BasicTeam.Id)
+		require.Nil(t, err)
+		require.Len(t, categories.Categories, 3)
+		assert.NotContains(t, categories.Categories[0].Channels, notFavorite.Id)
+	})
+}
+
+func TestSidebarCategoryChannels(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	channel1 := th.CreateChannel(th.BasicTeam)
+	channel2 := th.CreateChannel(th.BasicTeam)
+
+	custom, err := th.App.CreateSidebarCategory(th.BasicUser.Id, th.BasicTeam.Id, &model.SidebarCategoryWithChannels{
+		SidebarCategory: model.SidebarCategory{DisplayName: "Custom"},
+		Channels:        []string{channel2.Id, channel1.Id},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, model.SIDEBAR_CATEGORY_CUSTOM, custom.Type)
+
+	categories, err := th.App.GetSidebarCategories(th.BasicUser.Id, th.BasicTeam.Id)
+	require.Nil(t, err)
+	require.Len(t, categories.Categories, 4)
+	assert.Equal(t, custom.Id, categories.Order[3])
+	assert.Equal(t, []string{channel2.Id, channel1.Id}, categories.Categories[3].Channels)
+	assert.NotContains(t, categories.Categories[1].Channels, channel1.Id)
+
+	t.Run("moving a channel removes it from its old category", func(t *testing.T) {
+		favorites := categories.Categories[0]
+		favorites.Channels = []string{channel1.Id}
+
+		_, err := th.App.UpdateSidebarCategories(th.BasicUser.Id, th.BasicTeam.Id, []*model.SidebarCategoryWithChannels{favorites})
+		require.Nil(t, err)
+
+		category, err := th.App.GetSidebarCategory(th.BasicUser.Id, th.BasicTeam.Id, custom.Id)
+		require.Nil(t, err)
+		assert.Equal(t, []string{channel2.Id}, category.Channels)
+	})
+
+	t.Run("default categories can't be renamed", func(t *testing.T) {
+		favorites := categories.Categories[0]
+		favorites.DisplayName = "Renamed"
+
+		_, err := th.App.UpdateSidebarCategories(th.BasicUser.Id, th.BasicTeam.Id, []*model.SidebarCategoryWithChannels{favorites})
+		require.NotNil(t, err)
+	})
+
+	t.Run("channels must belong to the user", func(t *testing.T) {
+		other := th.createChannelWithAnotherUser(th.BasicTeam, model.CHANNEL_PRIVATE, th.BasicUser2.Id)
+
+		_, err := th.App.CreateSidebarCategory(th.BasicUser.Id, th.BasicTeam.Id, &model.SidebarCategoryWithChannels{
+			SidebarCategory: model.SidebarCategory{DisplayName: "Other"},
+			Channels:        []string{other.Id},
+		})
+		require.NotNil(t, err)
+	})
+
+	t.Run("leaving a channel removes it from its category", func(t *testing.T) {
+		require.Nil(t, th.App.RemoveUserFromChannel(th.BasicUser.Id, th.BasicUser.Id, channel2))
+
+		category, err := th.App.GetSidebarCategory(th.BasicUser.Id, th.BasicTeam.Id, custom.Id)
+		require.Nil(t, err)
+		assert.Empty(t, category.Channels)
+
+		th.AddUserToChannel(th.BasicUser, channel2)
+
+		categories, err := th.App.GetSidebarCategories(th.BasicUser.Id, th.BasicTeam.Id)
+		require.Nil(t, err)
+		assert.Contains(t, categories.Categories[1].Channels, channel2.Id)
+	})
+
+	t.Run("deleting a category returns its channels to the defaults", func(t *testing.T) {
+		category, err := th.App.CreateSidebarCategory(th.BasicUser.Id, th.BasicTeam.Id, &model.SidebarCategoryWithChannels{
+			SidebarCategory: model.SidebarCategory{DisplayName: "Temporary"},
+			Channels:        []string{channel2.Id},
+		})
+		require.Nil(t, err)
+
+		require.Nil(t, th.App.DeleteSidebarCategory(th.BasicUser.Id, th.BasicTeam.Id, category.Id))
+
+		categories, err := th.App.GetSidebarCategories(th.BasicUser.Id, th.BasicTeam.Id)
+		require.Nil(t, err)
+		assert.Len(t, categories.Categories, 4)
+		assert.Contains(t, categories.Categories[1].Channels, channel2.Id)
+
+		assert.NotNil(t, th.App.DeleteSidebarCategory(th.BasicUser.Id, th.BasicTeam.Id, categories.Categories[0].Id))
+	})
+}
+
+func TestUpdateSidebarCategoryOrder(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	custom, err := th.App.CreateSidebarCategory(th.BasicUser.Id, th.BasicTeam.Id, &model.SidebarCategoryWithChannels{
+		SidebarCategory: model.SidebarCategory{DisplayName: "Custom"},
+	})
+	require.Nil(t, err)
+
+	order, err := th.App.GetSidebarCategoryOrder(th.BasicUser.Id, th.BasicTeam.Id)
+	require.Nil(t, err)
+	require.Len(t, order, 4)
+	require.Equal(t, custom.Id, order[3])
+
+	newOrder := []string{custom.Id, order[2], order[0], order[1]}
+	require.Nil(t, th.App.UpdateSidebarCategoryOrder(th.BasicUser.Id, th.BasicTeam.Id, newOrder))
+
+	order, err = th.App.GetSidebarCategoryOrder(th.BasicUser.Id, th.BasicTeam.Id)
+	require.Nil(t, err)
+	assert.Equal(t, newOrder, order)
+
+	categories, err := th.App.GetSidebarCategories(th.BasicUser.Id, th.BasicTeam.Id)
+	require.Nil(t, err)
+	assert.Equal(t, custom.Id, categories.Categories[0].Id)
+	assert.Equal(t, model.SIDEBAR_CATEGORY_DIRECT_MESSAGES, categories.Categories[1].Type)
+
+	t.Run("order must include every category", func(t *testing.T) {
+		assert.NotNil(t, th.App.UpdateSidebarCategoryOrder(th.BasicUser.Id, th.BasicTeam.Id, newOrder[:3]))
+	})
+
+	t.Run("order can't repeat a category", func(t *testing.T) {
+		assert.NotNil(t, th.App.UpdateSidebarCategoryOrder(th.BasicUser.Id, th.BasicTeam.Id, []string{custom.Id, custom.Id, order[2], order[3]}))
+	})
+
+	t.Run("order can't include unknown categories", func(t *testing.T) {
+		assert.NotNil(t, th.App.UpdateSidebarCategoryOrder(th.BasicUser.Id, th.BasicTeam.Id, []string{model.NewId(), order[1], order[2], order[3]}))
+	})
+}
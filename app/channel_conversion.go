@@ -0,0 +1,108 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// ConvertChannel makes a public channel private or a private channel public on behalf of user, posting a system
+// message to the channel about it. The integrations that can read from or post to the channel are returned so that
+// they can be reviewed.
+func (a *App) ConvertChannel(channel *model.Channel, channelType string, user *model.User) (*model.ChannelConversion, *model.AppError) {
+	if channel.Type != model.CHANNEL_OPEN && channel.Type != model.CHANNEL_PRIVATE {
+		return nil, model.NewAppError("ConvertChannel", "app.channel.convert_channel.type.app_error", nil, "channel_id="+channel.Id, http.StatusBadRequest)
+	}
+
+	switch channelType {
+	case model.CHANNEL_PRIVATE:
+		if channel.Type == model.CHANNEL_PRIVATE {
+			return nil, model.NewAppError("ConvertChannel", "api.channel.convert_channel_to_private.private_channel_error", nil, "channel_id="+channel.Id, http.StatusBadRequest)
+		} else if channel.Name == model.DEFAULT_CHANNEL {
+			return nil, model.NewAppError("ConvertChannel", "api.channel.convert_channel_to_private.default_channel_error", nil, "channel_id="+channel.Id, http.StatusBadRequest)
+		}
+	case model.CHANNEL_OPEN:
+		if channel.Type == model.CHANNEL_OPEN {
+			return nil, model.NewAppError("ConvertChannel", "app.channel.convert_channel_to_public.public_channel.app_error", nil, "channel_id="+channel.Id, http.StatusBadRequest)
+		} else if !*a.Config().TeamSettings.EnablePrivateToPublicConversion {
+			return nil, model.NewAppError("ConvertChannel", "app.channel.convert_channel_to_public.disabled.app_error", nil, "channel_id="+channel.Id, http.StatusNotImplemented)
+		}
+	default:
+		return nil, model.NewAppError("ConvertChannel", "app.channel.convert_channel.type.app_error", nil, "channel_id="+channel.Id+", type="+channelType, http.StatusBadRequest)
+	}
+
+	channel = channel.DeepCopy()
+	channel.Type = channelType
+
+	channel, err := a.UpdateChannelPrivacy(channel, user)
+	if err != nil {
+		return nil, err
+	}
+
+	// Whether users can see the channel's members without belonging to it has changed
+	a.InvalidateCacheForChannelMembers(channel.Id)
+
+	// Sent to the whole team since users who aren't members may gain or lose access to the channel
+	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_CHANNEL_CONVERTED, channel.TeamId, "", "", nil)
+	message.Add("channel_id", channel.Id)
+	message.Add("channel_type", channel.Type)
+	a.Publish(message)
+
+	integrations, err := a.GetChannelIntegrations(channel)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ChannelConversion{
+		Channel:      channel,
+		Integrations: integrations,
+	}, nil
+}
+
+// GetChannelIntegrations returns the webhooks, slash commands and bots that can read from or post to a channel.
+// Their tokens are removed.
+func (a *App) GetChannelIntegrations(channel *model.Channel) (*model.ChannelIntegrations, *model.AppError) {
+	integrations := &model.ChannelIntegrations{
+		IncomingWebhooks: []*model.IncomingWebhook{},
+		OutgoingWebhooks: []*model.OutgoingWebhook{},
+		Commands:         []*model.Command{},
+		Bots:             []*model.User{},
+	}
+
+	result := <-a.Srv.Store.Webhook().GetIncomingByChannel(channel.Id)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	integrations.IncomingWebhooks = append(integrations.IncomingWebhooks, result.Data.([]*model.IncomingWebhook)...)
+
+	result = <-a.Srv.Store.Webhook().GetOutgoingByTeam(channel.TeamId, -1, -1)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	for _, hook := range result.Data.([]*model.OutgoingWebhook) {
+		if hook.ChannelId == channel.Id || hook.ChannelId == "" {
+			hook.Token = ""
+			integrations.OutgoingWebhooks = append(integrations.OutgoingWebhooks, hook)
+		}
+	}
+
+	result = <-a.Srv.Store.Command().GetByTeam(channel.TeamId)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	for _, command := range result.Data.([]*model.Command) {
+		command.Token = ""
+		integrations.Commands = append(integrations.Commands, command)
+	}
+
+	result = <-a.Srv.Store.User().GetBotsInChannel(channel.Id)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	integrations.Bots = append(integrations.Bots, result.Data.([]*model.User)...)
+
+	return integrations, nil
+}
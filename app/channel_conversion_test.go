@@ -0,0 +1,166 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func TestConvertChannel(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	enabled := *th.App.Config().TeamSettings.EnablePrivateToPublicConversion
+	defer th.App.UpdateConfig(func(cfg *model.Config) { *cfg.TeamSettings.EnablePrivateToPublicConversion = enabled })
+
+	t.Run("to private", func(t *testing.T) {
+		channel := th.createChannel(th.BasicTeam, model.CHANNEL_OPEN)
+
+		conversion, err := th.App.ConvertChannel(channel, model.CHANNEL_PRIVATE, th.BasicUser)
+		require.Nil(t, err)
+		assert.Equal(t, channel.Id, conversion.Id)
+		assert.Equal(t, model.CHANNEL_PRIVATE, conversion.Type)
+		assert.Equal(t, model.CHANNEL_OPEN, channel.Type, "shouldn't have modified the original channel")
+
+		stored, err := th.App.GetChannel(channel.Id)
+		require.Nil(t, err)
+		assert.Equal(t, model.CHANNEL_PRIVATE, stored.Type)
+
+		posts, err := th.App.GetPosts(channel.Id, 0, 1)
+		require.Nil(t, err)
+		require.Len(t, posts.Order, 1)
+		assert.Equal(t, model.POST_CHANGE_CHANNEL_PRIVACY, posts.Posts[posts.Order[0]].Type)
+
+		_, err = th.App.ConvertChannel(stored, model.CHANNEL_PRIVATE, th.BasicUser)
+		require.NotNil(t, err)
+		assert.Equal(t, "api.channel.convert_channel_to_private.private_channel_error", err.Id)
+	})
+
+	t.Run("default channel to private", func(t *testing.T) {
+		channel, err := th.App.GetChannelByName(model.DEFAULT_CHANNEL, th.BasicTeam.Id)
+		require.Nil(t, err)
+
+		_, err = th.App.ConvertChannel(channel, model.CHANNEL_PRIVATE, th.BasicUser)
+		require.NotNil(t, err)
+		assert.Equal(t, "api.channel.convert_channel_to_private.default_channel_error", err.Id)
+	})
+
+	t.Run("to public", func(t *testing.T) {
+		channel := th.createChannel(th.BasicTeam, model.CHANNEL_PRIVATE)
+
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.TeamSettings.EnablePrivateToPublicConversion = false })
+
+		_, err := th.App.ConvertChannel(channel, model.CHANNEL_OPEN, th.BasicUser)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.channel.convert_channel_to_public.disabled.app_error", err.Id)
+		assert.Equal(t, http.StatusNotImplemented, err.StatusCode)
+
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.TeamSettings.EnablePrivateToPublicConversion = true })
+
+		conversion, err := th.App.ConvertChannel(channel, model.CHANNEL_OPEN, th.BasicUser)
+		require.Nil(t, err)
+		assert.Equal(t, model.CHANNEL_OPEN, conversion.Type)
+
+		_, err = th.App.ConvertChannel(conversion.Channel, model.CHANNEL_OPEN, th.BasicUser)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.channel.convert_channel_to_public.public_channel.app_error", err.Id)
+	})
+
+	t.Run("invalid type", func(t *testing.T) {
+		_, err := th.App.ConvertChannel(th.BasicChannel, model.CHANNEL_DIRECT, th.BasicUser)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.channel.convert_channel.type.app_error", err.Id)
+
+		_, err = th.App.ConvertChannel(th.CreateDmChannel(th.BasicUser2), model.CHANNEL_OPEN, th.BasicUser)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.channel.convert_channel.type.app_error", err.Id)
+	})
+}
+
+func TestGetChannelIntegrations(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	channel := th.createChannel(th.BasicTeam, model.CHANNEL_OPEN)
+	otherChannel := th.createChannel(th.BasicTeam, model.CHANNEL_OPEN)
+
+	incoming := store.Must(th.App.Srv.Store.Webhook().SaveIncoming(&model.IncomingWebhook{
+		ChannelId: channel.Id,
+		TeamId:    th.BasicTeam.Id,
+		UserId:    th.BasicUser.Id,
+	})).(*model.IncomingWebhook)
+	store.Must(th.App.Srv.Store.Webhook().SaveIncoming(&model.IncomingWebhook{
+		ChannelId: otherChannel.Id,
+		TeamId:    th.BasicTeam.Id,
+		UserId:    th.BasicUser.Id,
+	}))
+
+	channelOutgoing := store.Must(th.App.Srv.Store.Webhook().SaveOutgoing(&model.OutgoingWebhook{
+		ChannelId:    channel.Id,
+		TeamId:       th.BasicTeam.Id,
+		CreatorId:    th.BasicUser.Id,
+		CallbackURLs: []string{"http://nowhere.com"},
+	})).(*model.OutgoingWebhook)
+	teamOutgoing := store.Must(th.App.Srv.Store.Webhook().SaveOutgoing(&model.OutgoingWebhook{
+		TeamId:       th.BasicTeam.Id,
+		CreatorId:    th.BasicUser.Id,
+		TriggerWords: []string{"trigger"},
+		CallbackURLs: []string{"http://nowhere.com"},
+	})).(*model.OutgoingWebhook)
+	store.Must(th.App.Srv.Store.Webhook().SaveOutgoing(&model.OutgoingWebhook{
+		ChannelId:    otherChannel.Id,
+		TeamId:       th.BasicTeam.Id,
+		CreatorId:    th.BasicUser.Id,
+		CallbackURLs: []string{"http://nowhere.com"},
+	}))
+
+	command := store.Must(th.App.Srv.Store.Command().Save(&model.Command{
+		TeamId:    th.BasicTeam.Id,
+		CreatorId: th.BasicUser.Id,
+		Method:    model.COMMAND_METHOD_POST,
+		Trigger:   "trigger" + model.NewId()[:10],
+		URL:       "http://nowhere.com",
+	})).(*model.Command)
+
+	bot := store.Must(th.App.Srv.Store.User().Save(&model.User{Email: th.MakeEmail(), Username: "bot" + model.NewId(), IsBot: true})).(*model.User)
+	th.LinkUserToTeam(bot, th.BasicTeam)
+	th.AddUserToChannel(bot, channel)
+
+	integrations, err := th.App.GetChannelIntegrations(channel)
+	require.Nil(t, err)
+
+	require.Len(t, integrations.IncomingWebhooks, 1)
+	assert.Equal(t, incoming.Id, integrations.IncomingWebhooks[0].Id)
+
+	outgoingIds := []string{}
+	for _, hook := range integrations.OutgoingWebhooks {
+		outgoingIds = append(outgoingIds, hook.Id)
+		assert.Equal(t, "", hook.Token)
+	}
+	assert.ElementsMatch(t, []string{channelOutgoing.Id, teamOutgoing.Id}, outgoingIds)
+
+	require.Len(t, integrations.Commands, 1)
+	assert.Equal(t, command.Id, integrations.Commands[0].Id)
+	assert.Equal(t, "", integrations.Commands[0].Token)
+
+	require.Len(t, integrations.Bots, 1)
+	assert.Equal(t, bot.Id, integrations.Bots[0].Id)
+
+	t.Run("no integrations", func(t *testing.T) {
+		integrations, err := th.App.GetChannelIntegrations(th.createChannel(th.CreateTeam(), model.CHANNEL_OPEN))
+		require.Nil(t, err)
+		assert.Empty(t, integrations.IncomingWebhooks)
+		assert.NotNil(t, integrations.IncomingWebhooks)
+		assert.Empty(t, integrations.OutgoingWebhooks)
+		assert.Empty(t, integrations.Commands)
+		assert.Empty(t, integrations.Bots)
+	})
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const (
+	CHANNEL_DELETION_BATCH_SIZE      = 100
+	CHANNEL_DELETION_FILE_BATCH_SIZE = 1000
+	CHANNEL_DELETION_POST_BATCH_SIZE = 1000
+)
+
+// MarkChannelForPermanentDeletion archives the channel, if it isn't already, and marks it to be permanently deleted
+// once the configured grace period has passed. Until then, it's hidden from the lists of archived channels and can
+// be restored.
+func (a *App) MarkChannelForPermanentDeletion(channel *model.Channel, userId string) (*model.Channel, *model.AppError) {
+	if channel.PermanentDeleteAt != 0 {
+		return nil, model.NewAppError("MarkChannelForPermanentDeletion", "app.channel.mark_for_permanent_deletion.already_marked.app_error", nil, "id="+channel.Id, http.StatusBadRequest)
+	}
+
+	if channel.DeleteAt == 0 {
+		if err := a.DeleteChannel(channel, userId); err != nil {
+			return nil, err
+		}
+	}
+
+	now := model.GetMillis()
+	permanentDeleteAt := now + int64(*a.Config().ServiceSettings.ChannelDeletionGracePeriodDays)*int64(24*time.Hour/time.Millisecond)
+	if result := <-a.Srv.Store.Channel().SetPermanentDeleteAt(channel.Id, permanentDeleteAt, now); result.Err != nil {
+		return nil, result.Err
+	}
+	a.InvalidateCacheForChannel(channel)
+
+	result := <-a.Srv.Store.Channel().GetFromMaster(channel.Id)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.Channel), nil
+}
+
+// permanentDeleteChannelFiles permanently deletes the files that were uploaded to the channel or attached to its
+// posts from both the database and the file store, returning how many were deleted.
+func (a *App) permanentDeleteChannelFiles(channel *model.Channel) (int64, *model.AppError) {
+	var deleted int64
+	for {
+		result := <-a.Srv.Store.FileInfo().PermanentDeleteForChannelBatch(channel.Id, CHANNEL_DELETION_FILE_BATCH_SIZE)
+		if result.Err != nil {
+			return deleted, result.Err
+		}
+
+		infos := result.Data.([]*model.FileInfo)
+		if len(infos) == 0 {
+			return deleted, nil
+		}
+		deleted += int64(len(infos))
+
+		// The storage used by files that were already deleted was released when they were
+		var released []*model.FileInfo
+		for _, info := range infos {
+			if info.DeleteAt == 0 {
+				info.ChannelId = channel.Id
+				released = append(released, info)
+			}
+		}
+		a.releaseFileStorageForFiles(released)
+
+		// The files are already gone from the database, so anything left in the file store is only logged
+		a.sharedFilesLock.Lock()
+		paths, err := a.unreferencedFilePaths(infos)
+		if err != nil {
+			mlog.Warn(fmt.Sprintf("Unable to check whether the files of a deleted channel are still used err=%v", err), mlog.String("channel_id", channel.Id))
+		}
+
+		for _, path := range paths {
+			if err := a.RemoveFile(path); err != nil {
+				mlog.Warn(fmt.Sprintf("Unable to remove a file of a deleted channel from the file store err=%v", err), mlog.String("channel_id", channel.Id), mlog.String("path", path))
+			}
+		}
+		a.sharedFilesLock.Unlock()
+	}
+}
+
+// PermanentDeleteDueChannels permanently deletes the channels that were marked for deletion at or before now, along
+// with their posts, files, webhooks and members. The optional progress callback is called with what's been done so
+// far before each channel is deleted, and stops the run early by returning false. If a channel can't be deleted,
+// the run stops with the error and the result's ChannelId is the channel that failed.
+func (a *App) PermanentDeleteDueChannels(now int64, progress func(result *model.ChannelDeletionResult) bool) (*model.ChannelDeletionResult, *model.AppError) {
+	result := &model.ChannelDeletionResult{}
+
+	for {
+		storeResult := <-a.Srv.Store.Channel().GetDueForPermanentDeletion(now, CHANNEL_DELETION_BATCH_SIZE)
+		if storeResult.Err != nil {
+			return result, storeResult.Err
+		}
+
+		// Deleted channels are no longer due, so each batch is made up of the ones that haven't been deleted yet
+		channels := storeResult.Data.([]*model.Channel)
+		if len(channels) == 0 {
+			break
+		}
+		result.ChannelsDue += len(channels)
+
+		for _, channel := range channels {
+			result.ChannelId = channel.Id
+			if progress != nil && !progress(result) {
+				return result, nil
+			}
+
+			filesDeleted, err := a.permanentDeleteChannel(channel)
+			result.FilesDeleted += filesDeleted
+			if err != nil {
+				return result, err
+			}
+			result.ChannelsDeleted++
+
+			mlog.Info("Permanently deleted a channel that was marked for deletion", mlog.String("channel_id", channel.Id), mlog.Int64("files_deleted", filesDeleted))
+		}
+	}
+
+	result.ChannelId = ""
+	return result, nil
+}
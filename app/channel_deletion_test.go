@@ -0,0 +1,218 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestMarkChannelForPermanentDeletion(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.ChannelDeletionGracePeriodDays = 7 })
+	day := int64(24 * time.Hour / time.Millisecond)
+
+	t.Run("marks and hides the channel", func(t *testing.T) {
+		channel := th.CreateChannel(th.BasicTeam)
+
+		before := model.GetMillis()
+		marked, err := th.App.MarkChannelForPermanentDeletion(channel, th.BasicUser.Id)
+		require.Nil(t, err)
+		assert.NotEqual(t, int64(0), marked.DeleteAt, "the channel should be archived")
+		assert.True(t, marked.PermanentDeleteAt >= before+7*day && marked.PermanentDeleteAt <= model.GetMillis()+7*day)
+
+		deleted, err := th.App.GetDeletedChannels(th.BasicTeam.Id, 0, 100)
+		require.Nil(t, err)
+		for _, c := range *deleted {
+			assert.NotEqual(t, channel.Id, c.Id, "channels pending deletion shouldn't be listed")
+		}
+
+		_, err = th.App.MarkChannelForPermanentDeletion(marked, th.BasicUser.Id)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.channel.mark_for_permanent_deletion.already_marked.app_error", err.Id)
+	})
+
+	t.Run("already archived", func(t *testing.T) {
+		channel := th.CreateChannel(th.BasicTeam)
+		require.Nil(t, th.App.DeleteChannel(channel, th.BasicUser.Id))
+
+		channel, err := th.App.GetChannel(channel.Id)
+		require.Nil(t, err)
+
+		marked, err := th.App.MarkChannelForPermanentDeletion(channel, th.BasicUser.Id)
+		require.Nil(t, err)
+		assert.Equal(t, channel.DeleteAt, marked.DeleteAt)
+		assert.NotEqual(t, int64(0), marked.PermanentDeleteAt)
+	})
+
+	t.Run("town square", func(t *testing.T) {
+		channel, err := th.App.GetChannelByName(model.DEFAULT_CHANNEL, th.BasicTeam.Id)
+		require.Nil(t, err)
+
+		_, err = th.App.MarkChannelForPermanentDeletion(channel, th.BasicUser.Id)
+		require.NotNil(t, err)
+		assert.Equal(t, "api.channel.delete_channel.cannot.app_error", err.Id)
+	})
+}
+
+func TestRestoreChannelPendingDeletion(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.ChannelDeletionGracePeriodDays = 7 })
+	day := int64(24 * time.Hour / time.Millisecond)
+
+	t.Run("within the grace period", func(t *testing.T) {
+		channel := th.CreateChannel(th.BasicTeam)
+		post := th.CreatePost(channel)
+
+		marked, err := th.App.MarkChannelForPermanentDeletion(channel, th.BasicUser.Id)
+		require.Nil(t, err)
+
+		restored, err := th.App.RestoreChannel(marked)
+		require.Nil(t, err)
+		assert.Equal(t, int64(0), restored.DeleteAt)
+		assert.Equal(t, int64(0), restored.PermanentDeleteAt)
+
+		result, err := th.App.PermanentDeleteDueChannels(model.GetMillis()+8*day, nil)
+		require.Nil(t, err)
+		assert.Equal(t, 0, result.ChannelsDeleted)
+
+		channel, err = th.App.GetChannel(channel.Id)
+		require.Nil(t, err)
+		assert.Equal(t, int64(0), channel.DeleteAt)
+		assert.Equal(t, int64(0), channel.PermanentDeleteAt)
+
+		_, err = th.App.GetSinglePost(post.Id)
+		assert.Nil(t, err, "the channel's posts should be kept")
+	})
+
+	t.Run("after the grace period", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.ChannelDeletionGracePeriodDays = 0 })
+		defer th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.ChannelDeletionGracePeriodDays = 7 })
+
+		channel := th.CreateChannel(th.BasicTeam)
+		marked, err := th.App.MarkChannelForPermanentDeletion(channel, th.BasicUser.Id)
+		require.Nil(t, err)
+
+		_, err = th.App.RestoreChannel(marked)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.channel.restore_channel.permanently_deleted.app_error", err.Id)
+		assert.Equal(t, http.StatusBadRequest, err.StatusCode)
+	})
+}
+
+func TestPermanentDeleteDueChannels(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		cfg.ServiceSettings.EnableIncomingWebhooks = true
+		cfg.ServiceSettings.EnableOutgoingWebhooks = true
+		*cfg.ServiceSettings.ChannelDeletionGracePeriodDays = 1
+	})
+	day := int64(24 * time.Hour / time.Millisecond)
+
+	channel := th.CreateChannel(th.BasicTeam)
+	_, err := th.App.AddUserToChannel(th.BasicUser2, channel)
+	require.Nil(t, err)
+
+	uploaded, err := th.App.DoUploadFile(time.Now(), th.BasicTeam.Id, channel.Id, th.BasicUser.Id, "uploaded.txt", []byte("data"))
+	require.Nil(t, err)
+	attached, err := th.App.DoUploadFile(time.Now(), th.BasicTeam.Id, channel.Id, th.BasicUser.Id, "attached.txt", []byte("data"))
+	require.Nil(t, err)
+
+	post, err := th.App.CreatePost(&model.Post{
+		ChannelId: channel.Id,
+		UserId:    th.BasicUser.Id,
+		Message:   "message",
+		FileIds:   []string{attached.Id},
+	}, channel, false)
+	require.Nil(t, err)
+
+	incoming, err := th.App.CreateIncomingWebhookForChannel(th.BasicUser.Id, channel, &model.IncomingWebhook{ChannelId: channel.Id})
+	require.Nil(t, err)
+	outgoing, err := th.App.CreateOutgoingWebhook(&model.OutgoingWebhook{
+		ChannelId:    channel.Id,
+		TeamId:       channel.TeamId,
+		CreatorId:    th.BasicUser.Id,
+		CallbackURLs: []string{"http://foo"},
+	})
+	require.Nil(t, err)
+
+	kept := th.CreateChannel(th.BasicTeam)
+	keptPost := th.CreatePost(kept)
+
+	_, err = th.App.MarkChannelForPermanentDeletion(channel, th.BasicUser.Id)
+	require.Nil(t, err)
+
+	t.Run("not yet due", func(t *testing.T) {
+		result, err := th.App.PermanentDeleteDueChannels(model.GetMillis(), nil)
+		require.Nil(t, err)
+		assert.Equal(t, 0, result.ChannelsDeleted)
+
+		_, err = th.App.GetChannel(channel.Id)
+		require.Nil(t, err)
+	})
+
+	t.Run("stopped by progress", func(t *testing.T) {
+		var progressed []string
+		result, err := th.App.PermanentDeleteDueChannels(model.GetMillis()+2*day, func(result *model.ChannelDeletionResult) bool {
+			progressed = append(progressed, result.ChannelId)
+			return false
+		})
+		require.Nil(t, err)
+		assert.Equal(t, []string{channel.Id}, progressed)
+		assert.Equal(t, 0, result.ChannelsDeleted)
+
+		_, err = th.App.GetChannel(channel.Id)
+		require.Nil(t, err)
+	})
+
+	t.Run("after the grace period", func(t *testing.T) {
+		result, err := th.App.PermanentDeleteDueChannels(model.GetMillis()+2*day, nil)
+		require.Nil(t, err)
+		assert.Equal(t, 1, result.ChannelsDue)
+		assert.Equal(t, 1, result.ChannelsDeleted)
+		assert.Equal(t, int64(2), result.FilesDeleted)
+		assert.Equal(t, "", result.ChannelId)
+
+		th.App.InvalidateCacheForChannel(channel)
+		_, err = th.App.GetChannel(channel.Id)
+		assert.NotNil(t, err, "the channel should be deleted")
+
+		_, err = th.App.GetSinglePost(post.Id)
+		assert.NotNil(t, err, "the channel's posts should be deleted")
+
+		for _, info := range []*model.FileInfo{uploaded, attached} {
+			_, err = th.App.GetFileInfo(info.Id)
+			assert.NotNil(t, err, "the channel's file infos should be deleted")
+
+			_, err = th.App.ReadFile(info.Path)
+			assert.NotNil(t, err, "the channel's files should be removed from the file store")
+		}
+
+		_, err = th.App.GetIncomingWebhook(incoming.Id)
+		assert.NotNil(t, err, "the channel's incoming webhooks should be deleted")
+		_, err = th.App.GetOutgoingWebhook(outgoing.Id)
+		assert.NotNil(t, err, "the channel's outgoing webhooks should be deleted")
+
+		members, err := th.App.GetChannelMembersPage(channel.Id, 0, 100)
+		require.Nil(t, err)
+		assert.Empty(t, *members, "the channel's members should be deleted")
+
+		_, err = th.App.GetChannel(kept.Id)
+		assert.Nil(t, err)
+		_, err = th.App.GetSinglePost(keptPost.Id)
+		assert.Nil(t, err, "other channels should be left alone")
+	})
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+// updateUsernameHistory records a user's old username after they've changed it so that direct channels with them can
+// still be found by it.
+func (a *App) updateUsernameHistory(user *model.User, oldUsername string) {
+	if user.Username == oldUsername {
+		return
+	}
+
+	history := &model.UsernameHistory{
+		Username: oldUsername,
+		UserId:   user.Id,
+	}
+	if result := <-a.Srv.Store.User().SaveUsernameHistory(history, model.USERNAME_HISTORY_MAX_PER_USER); result.Err != nil {
+		mlog.Warn(fmt.Sprintf("Failed to save the old username of a user: %v", result.Err.Error()), mlog.String("user_id", user.Id))
+	}
+}
+
+// SetDirectChannelDisplayName returns a copy of a direct channel with DirectDisplayName set to the display name of
+// the user on the other side of it from userId. Any other channel is returned as it is.
+func (a *App) SetDirectChannelDisplayName(channel *model.Channel, userId string) *model.Channel {
+	otherUserId := channel.GetOtherUserIdForDM(userId)
+	if otherUserId == "" {
+		return channel
+	}
+
+	// The channel may be shared with the cache
+	channel = channel.DeepCopy()
+
+	if otherUser, err := a.GetUser(otherUserId); err == nil {
+		channel.DirectDisplayName = otherUser.GetDisplayName(*a.Config().TeamSettings.TeammateNameDisplay)
+	} else if err.Id == store.MISSING_ACCOUNT_ERROR {
+		channel.DirectDisplayName = utils.T("app.channel.direct_display_name.deleted_user")
+	} else {
+		mlog.Warn(fmt.Sprintf("Failed to get the other user of a direct channel: %v", err.Error()), mlog.String("channel_id", channel.Id))
+	}
+
+	return channel
+}
+
+// LookupDirectChannel returns the direct channel between two users, with DirectDisplayName set for userId. If there
+// isn't one yet, it's only created when create is true.
+func (a *App) LookupDirectChannel(userId, otherUserId string, create bool) (*model.Channel, *model.AppError) {
+	var channel *model.Channel
+
+	if result := <-a.Srv.Store.Channel().GetByName("", model.GetDMNameFromIds(userId, otherUserId), true); result.Err != nil && result.Err.Id == store.MISSING_CHANNEL_ERROR {
+		if !create {
+			result.Err.StatusCode = http.StatusNotFound
+			return nil, result.Err
+		}
+
+		var err *model.AppError
+		if channel, err = a.CreateDirectChannel(userId, otherUserId); err != nil {
+			return nil, err
+		}
+	} else if result.Err != nil {
+		return nil, result.Err
+	} else {
+		channel = result.Data.(*model.Channel)
+	}
+
+	return a.SetDirectChannelDisplayName(channel, userId), nil
+}
+
+// GetDirectChannelByUsername returns the existing direct channel between a user and the user with the given username.
+// If no one has that username, the user who used to have it is looked for instead and RedirectedFrom is set to the
+// "@username" that the channel was requested by.
+func (a *App) GetDirectChannelByUsername(userId, username string) (*model.Channel, *model.AppError) {
+	redirectedFrom := ""
+
+	otherUser, err := a.GetUserByUsername(username)
+	if err != nil {
+		result := <-a.Srv.Store.User().GetUsernameHistoryByUsername(username)
+		if result.Err != nil {
+			return nil, err
+		}
+
+		if otherUser, err = a.GetUser(result.Data.(*model.UsernameHistory).UserId); err != nil {
+			return nil, err
+		}
+		redirectedFrom = "@" + username
+	}
+
+	channel, err := a.LookupDirectChannel(userId, otherUser.Id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// The channel was copied from the cache when its display name was set
+	channel.RedirectedFrom = redirectedFrom
+
+	return channel, nil
+}
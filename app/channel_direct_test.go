@@ -0,0 +1,139 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestGetDirectChannelByUsername(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.TeamSettings.TeammateNameDisplay = model.SHOW_USERNAME })
+
+	user := th.CreateUser()
+	dm := th.CreateDmChannel(user)
+
+	t.Run("current username", func(t *testing.T) {
+		channel, err := th.App.GetDirectChannelByUsername(th.BasicUser.Id, user.Username)
+		require.Nil(t, err)
+		assert.Equal(t, dm.Id, channel.Id)
+		assert.Equal(t, user.Username, channel.DirectDisplayName)
+		assert.Equal(t, "", channel.RedirectedFrom)
+	})
+
+	t.Run("renamed user", func(t *testing.T) {
+		oldUsername := user.Username
+		user.Username = "renamed" + model.NewId()
+		_, err := th.App.UpdateUser(user, false)
+		require.Nil(t, err)
+
+		channel, err := th.App.GetDirectChannelByUsername(th.BasicUser.Id, oldUsername)
+		require.Nil(t, err)
+		assert.Equal(t, dm.Id, channel.Id)
+		assert.Equal(t, user.Username, channel.DirectDisplayName)
+		assert.Equal(t, "@"+oldUsername, channel.RedirectedFrom)
+
+		channel, err = th.App.GetDirectChannelByUsername(th.BasicUser.Id, user.Username)
+		require.Nil(t, err)
+		assert.Equal(t, dm.Id, channel.Id)
+		assert.Equal(t, "", channel.RedirectedFrom)
+	})
+
+	t.Run("deactivated user", func(t *testing.T) {
+		deactivated := th.CreateUser()
+		deactivatedDm := th.CreateDmChannel(deactivated)
+		_, err := th.App.UpdateActive(deactivated, false)
+		require.Nil(t, err)
+
+		channel, err := th.App.GetDirectChannelByUsername(th.BasicUser.Id, deactivated.Username)
+		require.Nil(t, err)
+		assert.Equal(t, deactivatedDm.Id, channel.Id)
+		assert.Equal(t, deactivated.Username, channel.DirectDisplayName)
+	})
+
+	t.Run("anonymized user", func(t *testing.T) {
+		anonymized := th.CreateUser()
+		th.CreateDmChannel(anonymized)
+
+		oldUsername := anonymized.Username
+		anonymized.Username = "renamed" + model.NewId()
+		_, err := th.App.UpdateUser(anonymized, false)
+		require.Nil(t, err)
+
+		_, err = th.App.AnonymizeUser(anonymized.Id, false, false)
+		require.Nil(t, err)
+
+		_, err = th.App.GetDirectChannelByUsername(th.BasicUser.Id, oldUsername)
+		require.NotNil(t, err, "the old usernames of an anonymized user shouldn't lead to them")
+	})
+
+	t.Run("no direct channel", func(t *testing.T) {
+		other := th.CreateUser()
+
+		_, err := th.App.GetDirectChannelByUsername(th.BasicUser.Id, other.Username)
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusNotFound, err.StatusCode)
+
+		_, err = th.App.GetDirectChannelByUsername(th.BasicUser.Id, "missing"+model.NewId())
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusNotFound, err.StatusCode)
+	})
+}
+
+func TestLookupDirectChannel(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.TeamSettings.TeammateNameDisplay = model.SHOW_FULLNAME })
+
+	user := th.CreateUser()
+	user.FirstName = "Direct"
+	user.LastName = "User"
+	user, err := th.App.UpdateUser(user, false)
+	require.Nil(t, err)
+
+	_, err = th.App.LookupDirectChannel(th.BasicUser.Id, user.Id, false)
+	require.NotNil(t, err, "the channel shouldn't be created without create")
+	assert.Equal(t, http.StatusNotFound, err.StatusCode)
+
+	result := <-th.App.Srv.Store.Channel().GetByName("", model.GetDMNameFromIds(th.BasicUser.Id, user.Id), false)
+	require.NotNil(t, result.Err)
+
+	created, err := th.App.LookupDirectChannel(th.BasicUser.Id, user.Id, true)
+	require.Nil(t, err)
+	assert.Equal(t, model.CHANNEL_DIRECT, created.Type)
+	assert.Equal(t, "Direct User", created.DirectDisplayName)
+
+	channel, err := th.App.LookupDirectChannel(user.Id, th.BasicUser.Id, false)
+	require.Nil(t, err)
+	assert.Equal(t, created.Id, channel.Id)
+	assert.Equal(t, th.BasicUser.GetDisplayName(model.SHOW_FULLNAME), channel.DirectDisplayName)
+}
+
+func TestSetDirectChannelDisplayName(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	user := th.CreateUser()
+	dm := th.CreateDmChannel(user)
+
+	channel := th.App.SetDirectChannelDisplayName(dm, th.BasicUser.Id)
+	assert.Equal(t, user.GetDisplayName(*th.App.Config().TeamSettings.TeammateNameDisplay), channel.DirectDisplayName)
+	assert.Equal(t, "", dm.DirectDisplayName, "the channel should have been copied")
+
+	require.Nil(t, th.App.PermanentDeleteUser(user))
+
+	channel = th.App.SetDirectChannelDisplayName(dm, th.BasicUser.Id)
+	assert.Equal(t, "Deleted User", channel.DirectDisplayName)
+
+	assert.Equal(t, th.BasicChannel, th.App.SetDirectChannelDisplayName(th.BasicChannel, th.BasicUser.Id))
+}
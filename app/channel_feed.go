@@ -0,0 +1,233 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils/markdown"
+)
+
+const CHANNEL_FEED_TITLE_MAX_RUNES = 100
+
+type atomFeed struct {
+	XMLName  xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+	Id       string       `xml:"id"`
+	Title    string       `xml:"title"`
+	Subtitle string       `xml:"subtitle,omitempty"`
+	Updated  string       `xml:"updated"`
+	Links    []*atomLink  `xml:"link"`
+	Entries  []*atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	Id        string       `xml:"id"`
+	Title     string       `xml:"title"`
+	Published string       `xml:"published"`
+	Updated   string       `xml:"updated"`
+	Author    *atomAuthor  `xml:"author"`
+	Links     []*atomLink  `xml:"link"`
+	Content   *atomContent `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// GenerateChannelFeedToken signs a channel's feed key with the server's public link salt, so that the token can't be
+// worked out from the key alone and changing the salt revokes every feed's token at once.
+func GenerateChannelFeedToken(channelId, feedKey, salt string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(channelId))
+	mac.Write([]byte(feedKey))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// checkChannelFeedAllowed returns an error if a feed can't be turned on for the channel.
+func (a *App) checkChannelFeedAllowed(channel *model.Channel) *model.AppError {
+	if !*a.Config().ServiceSettings.EnableChannelFeeds {
+		return model.NewAppError("checkChannelFeedAllowed", "app.channel.feed.disabled.app_error", nil, "", http.StatusNotImplemented)
+	}
+
+	if channel.Type != model.CHANNEL_OPEN {
+		return model.NewAppError("checkChannelFeedAllowed", "app.channel.feed.not_public.app_error", nil, "channel_id="+channel.Id, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+// GetChannelFeed returns the address of a channel's feed, including the token needed to read it.
+func (a *App) GetChannelFeed(channel *model.Channel, siteURL string) (*model.ChannelFeed, *model.AppError) {
+	if err := a.checkChannelFeedAllowed(channel); err != nil {
+		return nil, err
+	}
+
+	if !channel.EnableFeed || channel.FeedKey == "" {
+		return nil, model.NewAppError("GetChannelFeed", "app.channel.feed.not_enabled.app_error", nil, "channel_id="+channel.Id, http.StatusNotFound)
+	}
+
+	token := GenerateChannelFeedToken(channel.Id, channel.FeedKey, *a.Config().FileSettings.PublicLinkSalt)
+
+	return &model.ChannelFeed{
+		ChannelId: channel.Id,
+		Token:     token,
+		Url:       channelFeedURL(siteURL, channel.Id) + "?token=" + token,
+	}, nil
+}
+
+// RegenerateChannelFeedToken replaces the token for a channel's feed, so that the old one can no longer be used
+// to read it.
+func (a *App) RegenerateChannelFeedToken(channel *model.Channel, siteURL string) (*model.ChannelFeed, *model.AppError) {
+	if err := a.checkChannelFeedAllowed(channel); err != nil {
+		return nil, err
+	}
+
+	if !channel.EnableFeed {
+		return nil, model.NewAppError("RegenerateChannelFeedToken", "app.channel.feed.not_enabled.app_error", nil, "channel_id="+channel.Id, http.StatusNotFound)
+	}
+
+	channel.FeedKey = model.NewId()
+
+	channel, err := a.UpdateChannel(channel)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.GetChannelFeed(channel, siteURL)
+}
+
+// CheckChannelFeedToken returns an error unless the channel has a feed that can be read with the given token. The
+// channel's type is checked again so that a private channel is never exposed, even if its feed wasn't turned off.
+func (a *App) CheckChannelFeedToken(channel *model.Channel, token string) *model.AppError {
+	if !*a.Config().ServiceSettings.EnableChannelFeeds {
+		return model.NewAppError("CheckChannelFeedToken", "app.channel.feed.disabled.app_error", nil, "", http.StatusNotImplemented)
+	}
+
+	if channel.Type != model.CHANNEL_OPEN || !channel.EnableFeed || channel.FeedKey == "" || channel.DeleteAt != 0 || token == "" {
+		return model.NewAppError("CheckChannelFeedToken", "app.channel.feed.invalid_token.app_error", nil, "channel_id="+channel.Id, http.StatusForbidden)
+	}
+
+	expected := GenerateChannelFeedToken(channel.Id, channel.FeedKey, *a.Config().FileSettings.PublicLinkSalt)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		return model.NewAppError("CheckChannelFeedToken", "app.channel.feed.invalid_token.app_error", nil, "channel_id="+channel.Id, http.StatusForbidden)
+	}
+
+	return nil
+}
+
+// GetChannelFeedAtom renders the latest posts in a channel as an Atom feed, returning it along with the time that
+// the feed was last updated. System messages are left out.
+func (a *App) GetChannelFeedAtom(channel *model.Channel, siteURL string) ([]byte, int64, *model.AppError) {
+	team, err := a.GetTeam(channel.TeamId)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	posts, err := a.GetPosts(channel.Id, 0, *a.Config().ServiceSettings.ChannelFeedPostCount)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	feedURL := channelFeedURL(siteURL, channel.Id)
+	feed := &atomFeed{
+		Id:       feedURL,
+		Title:    channel.DisplayName,
+		Subtitle: channel.Purpose,
+		Links: []*atomLink{
+			{Rel: "alternate", Type: "text/html", Href: siteURL + "/" + team.Name + "/channels/" + channel.Name},
+		},
+	}
+
+	updatedAt := channel.CreateAt
+	authors := make(map[string]string)
+	for _, postId := range posts.Order {
+		post := posts.Posts[postId]
+		if post == nil || post.IsSystemMessage() {
+			continue
+		}
+
+		author, ok := authors[post.UserId]
+		if !ok {
+			author = post.UserId
+			if user, err := a.GetUser(post.UserId); err == nil {
+				author = user.GetDisplayName(*a.Config().TeamSettings.TeammateNameDisplay)
+			}
+			authors[post.UserId] = author
+		}
+
+		if overrideUsername, ok := post.Props["override_username"].(string); ok && overrideUsername != "" && post.Props["from_webhook"] == "true" {
+			author = overrideUsername
+		}
+
+		// Posts are updated when they're reacted to or pinned as well as when they're edited
+		if post.UpdateAt > updatedAt {
+			updatedAt = post.UpdateAt
+		}
+
+		feed.Entries = append(feed.Entries, &atomEntry{
+			Id:        siteURL + model.API_URL_SUFFIX + "/posts/" + post.Id,
+			Title:     channelFeedEntryTitle(post.Message, author),
+			Published: formatAtomTime(post.CreateAt),
+			Updated:   formatAtomTime(post.UpdateAt),
+			Author:    &atomAuthor{Name: author},
+			Links: []*atomLink{
+				{Rel: "alternate", Type: "text/html", Href: siteURL + "/" + team.Name + "/pl/" + post.Id},
+			},
+			Content: &atomContent{Type: "html", Body: markdown.RenderSafeHTML(post.Message)},
+		})
+	}
+
+	feed.Updated = formatAtomTime(updatedAt)
+
+	data, xmlErr := xml.MarshalIndent(feed, "", "  ")
+	if xmlErr != nil {
+		return nil, 0, model.NewAppError("GetChannelFeedAtom", "app.channel.feed.render.app_error", nil, xmlErr.Error(), http.StatusInternalServerError)
+	}
+
+	return append([]byte(xml.Header), data...), updatedAt, nil
+}
+
+func channelFeedURL(siteURL string, channelId string) string {
+	return siteURL + model.API_URL_SUFFIX + "/channels/" + channelId + "/feed.atom"
+}
+
+// channelFeedEntryTitle uses the first line of a post's message as its title, falling back to the author's name
+// for posts without any text.
+func channelFeedEntryTitle(message string, author string) string {
+	title := strings.TrimSpace(strings.SplitN(strings.TrimSpace(message), "\n", 2)[0])
+	if title == "" {
+		return author
+	}
+
+	if utf8.RuneCountInString(title) > CHANNEL_FEED_TITLE_MAX_RUNES {
+		title = string([]rune(title)[:CHANNEL_FEED_TITLE_MAX_RUNES-1]) + "…"
+	}
+
+	return title
+}
+
+func formatAtomTime(millis int64) string {
+	return time.Unix(0, millis*int64(time.Millisecond)).UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestGetChannelFeedAtom(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableChannelFeeds = true })
+
+	channel := th.CreateChannel(th.BasicTeam)
+	channel, err := th.App.PatchChannel(channel, &model.ChannelPatch{EnableFeed: model.NewBool(true)}, th.BasicUser.Id)
+	require.Nil(t, err)
+	require.NotEmpty(t, channel.FeedKey)
+
+	first := th.CreatePost(channel)
+	second, err := th.App.CreatePostAsUser(&model.Post{
+		UserId:    th.BasicUser2.Id,
+		ChannelId: channel.Id,
+		Message:   "**release** notes\nmore details [x](javascript:alert(1))",
+	})
+	require.Nil(t, err)
+	_, err = th.App.CreatePostAsUser(&model.Post{
+		UserId:    th.BasicUser.Id,
+		ChannelId: channel.Id,
+		Type:      model.POST_HEADER_CHANGE,
+		Message:   "header changed",
+	})
+	require.Nil(t, err)
+
+	data, updatedAt, err := th.App.GetChannelFeedAtom(channel, "http://example.com")
+	require.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(data), xml.Header))
+	assert.True(t, updatedAt >= second.UpdateAt)
+
+	var feed atomFeed
+	require.NoError(t, xml.Unmarshal(data, &feed))
+	assert.Equal(t, channel.DisplayName, feed.Title)
+	assert.Equal(t, "http://example.com/api/v4/channels/"+channel.Id+"/feed.atom", feed.Id)
+	require.Len(t, feed.Entries, 2, "system messages should be left out")
+
+	entry := feed.Entries[0]
+	assert.Equal(t, "http://example.com/api/v4/posts/"+second.Id, entry.Id)
+	assert.Equal(t, "**release** notes", entry.Title)
+	assert.Equal(t, th.BasicUser2.GetDisplayName(model.SHOW_USERNAME), entry.Author.Name)
+	assert.Equal(t, "html", entry.Content.Type)
+	assert.Contains(t, entry.Content.Body, "<p>**release** notes\nmore details x</p>")
+	assert.NotContains(t, entry.Content.Body, "javascript:", "links with unsafe URLs shouldn't be included")
+	require.Len(t, entry.Links, 1)
+	assert.Equal(t, "http://example.com/"+th.BasicTeam.Name+"/pl/"+second.Id, entry.Links[0].Href)
+
+	assert.Equal(t, "http://example.com/api/v4/posts/"+first.Id, feed.Entries[1].Id)
+}
+
+func TestCheckChannelFeedToken(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	channel := th.CreateChannel(th.BasicTeam)
+	_, err := th.App.PatchChannel(channel, &model.ChannelPatch{EnableFeed: model.NewBool(true)}, th.BasicUser.Id)
+	require.NotNil(t, err, "feeds can't be enabled while they're disabled on the server")
+	assert.Equal(t, "app.channel.feed.disabled.app_error", err.Id)
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableChannelFeeds = true })
+
+	_, err = th.App.PatchChannel(th.BasicChannel, &model.ChannelPatch{EnableFeed: model.NewBool(true)}, th.BasicUser.Id)
+	require.Nil(t, err)
+
+	private := th.createChannel(th.BasicTeam, model.CHANNEL_PRIVATE)
+	_, err = th.App.PatchChannel(private, &model.ChannelPatch{EnableFeed: model.NewBool(true)}, th.BasicUser.Id)
+	require.NotNil(t, err)
+	assert.Equal(t, "app.channel.feed.not_public.app_error", err.Id)
+
+	channel, err = th.App.PatchChannel(channel, &model.ChannelPatch{EnableFeed: model.NewBool(true)}, th.BasicUser.Id)
+	require.Nil(t, err)
+
+	feed, err := th.App.GetChannelFeed(channel, "http://example.com")
+	require.Nil(t, err)
+	assert.Equal(t, "http://example.com/api/v4/channels/"+channel.Id+"/feed.atom?token="+feed.Token, feed.Url)
+	require.Nil(t, th.App.CheckChannelFeedToken(channel, feed.Token))
+	require.NotNil(t, th.App.CheckChannelFeedToken(channel, ""))
+	require.NotNil(t, th.App.CheckChannelFeedToken(channel, feed.Token+"x"))
+
+	regenerated, err := th.App.RegenerateChannelFeedToken(channel, "http://example.com")
+	require.Nil(t, err)
+	assert.NotEqual(t, feed.Token, regenerated.Token)
+
+	channel, err = th.App.GetChannel(channel.Id)
+	require.Nil(t, err)
+	require.NotNil(t, th.App.CheckChannelFeedToken(channel, feed.Token), "the old token should be revoked")
+	require.Nil(t, th.App.CheckChannelFeedToken(channel, regenerated.Token))
+
+	channel, err = th.App.PatchChannel(channel, &model.ChannelPatch{EnableFeed: model.NewBool(false)}, th.BasicUser.Id)
+	require.Nil(t, err)
+	assert.Empty(t, channel.FeedKey)
+	require.NotNil(t, th.App.CheckChannelFeedToken(channel, regenerated.Token))
+}
+
+func TestGenerateChannelFeedToken(t *testing.T) {
+	channelId := model.NewId()
+	feedKey := model.NewId()
+
+	token := GenerateChannelFeedToken(channelId, feedKey, "salt")
+	assert.Equal(t, token, GenerateChannelFeedToken(channelId, feedKey, "salt"))
+	assert.NotEqual(t, token, GenerateChannelFeedToken(channelId, model.NewId(), "salt"))
+	assert.NotEqual(t, token, GenerateChannelFeedToken(channelId, feedKey, "other salt"))
+	assert.NotContains(t, token, feedKey)
+}
+
+func TestChannelFeedEntryTitle(t *testing.T) {
+	assert.Equal(t, "first line", channelFeedEntryTitle("  first line \nsecond line", "author"))
+	assert.Equal(t, "author", channelFeedEntryTitle(" \n ", "author"))
+
+	title := channelFeedEntryTitle(strings.Repeat("a", CHANNEL_FEED_TITLE_MAX_RUNES+10), "author")
+	assert.Equal(t, strings.Repeat("a", CHANNEL_FEED_TITLE_MAX_RUNES-1)+"…", title)
+}
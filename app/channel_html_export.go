@@ -0,0 +1,644 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils/markdown"
+)
+
+const CHANNEL_HTML_EXPORT_BATCH_SIZE = 1000
+
+// channelHtmlExportTextPattern matches the mentions and emoji in the plain text of a message.
+var channelHtmlExportTextPattern = regexp.MustCompile(`@[a-zA-Z0-9.\-_]+|:[a-zA-Z0-9_+\-]+:`)
+
+var channelHtmlExportTemplates = template.Must(template.New("channel_html_export").Parse(`
+{{define "head"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 0 auto; max-width: 960px; padding: 0 16px; color: #333; }
+a { color: #2389d7; }
+.post { border-bottom: 1px solid #eee; padding: 8px 0; }
+.post--reply { margin-left: 32px; }
+.post--system { color: #888; font-style: italic; }
+.post__user { font-weight: bold; }
+.post__user--deactivated { color: #888; }
+.post__header time, .post__edited { color: #888; font-size: 0.85em; }
+.post__files { list-style: none; padding: 0; }
+.post__files img { max-width: 120px; max-height: 100px; }
+.mention { color: #2389d7; }
+.emoji { height: 1.2em; vertical-align: middle; }
+.file--missing { color: #888; text-decoration: line-through; }
+.participants { border-collapse: collapse; }
+.participants th, .participants td { border: 1px solid #eee; padding: 4px 8px; text-align: left; }
+</style>
+</head>
+<body>
+{{end}}
+
+{{define "month_header"}}{{template "head" .}}<header>
+<p><a href="index.html">{{.ChannelName}}</a></p>
+<h1>{{.MonthLabel}}</h1>
+</header>
+<main>
+{{end}}
+
+{{define "post"}}<article class="post{{if .IsSystem}} post--system{{end}}{{if .RootHref}} post--reply{{end}}" id="post-{{.Id}}">
+<div class="post__header"><span class="post__user{{if .IsDeactivated}} post__user--deactivated{{end}}"{{if .FullName}} title="{{.FullName}}"{{end}}>{{.Username}}</span> <time datetime="{{.DateTime}}">{{.Time}}</time>{{if .IsEdited}} <span class="post__edited">(edited)</span>{{end}}{{if .RootHref}} <a class="post__root" href="{{.RootHref}}">in reply to a thread</a>{{end}}</div>
+<div class="post__message">{{.Message}}</div>
+{{if .Files}}<ul class="post__files">{{range .Files}}<li>{{if .IsMissing}}<span class="file--missing">{{.Name}}</span>{{else}}<a href="{{.Href}}">{{if .ThumbnailHref}}<img src="{{.ThumbnailHref}}" alt="{{.Name}}"><br>{{end}}{{.Name}}</a>{{end}}</li>{{end}}</ul>
+{{end}}</article>
+{{end}}
+
+{{define "month_footer"}}</main>
+<footer><p><a href="index.html">Back to {{.ChannelName}}</a></p></footer>
+</body>
+</html>
+{{end}}
+
+{{define "index"}}{{template "head" .}}<header>
+<h1>{{.ChannelName}}</h1>
+{{if .TeamName}}<p>{{.TeamName}}</p>{{end}}
+</header>
+<main>
+{{if .Purpose}}<section class="purpose">{{.Purpose}}</section>{{end}}
+{{if .Header}}<section class="header">{{.Header}}</section>{{end}}
+<h2>Months</h2>
+{{if .Months}}<ul class="months">
+{{range .Months}}<li><a href="{{.FileName}}">{{.Month}}</a> ({{.PostCount}} posts)</li>
+{{end}}</ul>{{else}}<p>There are no posts in this channel.</p>{{end}}
+<h2>Participants</h2>
+<table class="participants">
+<thead><tr><th>Username</th><th>Name</th><th>Posts</th><th>First post</th><th>Last post</th></tr></thead>
+<tbody>
+{{range .Participants}}<tr class="participant" id="user-{{.UserId}}"><td>{{.Username}}{{if .IsDeactivated}} (deactivated){{end}}</td><td>{{.FullName}}</td><td>{{.PostCount}}</td><td>{{.FirstPost}}</td><td>{{.LastPost}}</td></tr>
+{{end}}</tbody>
+</table>
+</main>
+<footer><p>{{.PostCount}} posts and {{.FileCount}} files exported at {{.ExportedAt}}</p></footer>
+</body>
+</html>
+{{end}}`))
+
+type channelHtmlExportPage struct {
+	Title       string
+	ChannelName string
+	MonthLabel  string
+}
+
+type channelHtmlExportPost struct {
+	Id            string
+	Username      string
+	FullName      string
+	IsDeactivated bool
+	IsSystem      bool
+	IsEdited      bool
+	DateTime      string
+	Time          string
+	RootHref      string
+	Message       template.HTML
+	Files         []*channelHtmlExportFile
+}
+
+type channelHtmlExportFile struct {
+	Name          string
+	Href          string
+	ThumbnailHref string
+	IsMissing     bool
+}
+
+type channelHtmlExportIndex struct {
+	channelHtmlExportPage
+	TeamName     string
+	Purpose      template.HTML
+	Header       template.HTML
+	Months       []*model.ChannelHtmlExportMonth
+	Participants []*channelHtmlExportIndexParticipant
+	PostCount    int
+	FileCount    int
+	ExportedAt   string
+}
+
+type channelHtmlExportIndexParticipant struct {
+	*model.ChannelHtmlExportParticipant
+	FirstPost string
+	LastPost  string
+}
+
+// ExportChannelHtml writes the posts in a channel to dir as a static HTML archive that can be browsed without a
+// server. The posts are split into a page for each month, which are listed by an index page along with the people
+// who made them, and the files attached to the posts are copied alongside the pages. Posts are read from the store
+// in batches and written out as they're read, so the size of the channel doesn't affect how much memory is used.
+// Times are given in location.
+func (a *App) ExportChannelHtml(channel *model.Channel, dir string, location *time.Location) (*model.ChannelHtmlExport, *model.AppError) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, model.NewAppError("ExportChannelHtml", "app.channel_html_export.write.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	e := newChannelHtmlExporter(a, channel, dir, location)
+
+	err := e.writePosts()
+	if closeErr := e.closeMonth(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := e.writeIndex(); err != nil {
+		return nil, err
+	}
+
+	return e.export, nil
+}
+
+// channelHtmlExporter writes the pages of a channel's HTML archive. Only the page for the month that's being written
+// is kept open, while the users and emoji that posts refer to are remembered so that they're only looked up once.
+type channelHtmlExporter struct {
+	app         *App
+	channel     *model.Channel
+	channelName string
+	dir         string
+	location    *time.Location
+
+	export       *model.ChannelHtmlExport
+	participants map[string]*model.ChannelHtmlExportParticipant
+
+	month     *model.ChannelHtmlExportMonth
+	monthFile *os.File
+	writer    *bufio.Writer
+
+	users        map[string]*model.User
+	usernames    map[string]*model.User
+	emojis       map[string]string
+	lastRootId   string
+	lastRootHref string
+}
+
+func newChannelHtmlExporter(a *App, channel *model.Channel, dir string, location *time.Location) *channelHtmlExporter {
+	channelName := channel.DisplayName
+	if channelName == "" {
+		channelName = channel.Name
+	}
+
+	return &channelHtmlExporter{
+		app:          a,
+		channel:      channel,
+		channelName:  channelName,
+		dir:          dir,
+		location:     location,
+		export:       &model.ChannelHtmlExport{ChannelId: channel.Id},
+		participants: make(map[string]*model.ChannelHtmlExportParticipant),
+		users:        make(map[string]*model.User),
+		usernames:    make(map[string]*model.User),
+		emojis:       make(map[string]string),
+	}
+}
+
+func newChannelHtmlExportWriteError(err error) *model.AppError {
+	return model.NewAppError("ExportChannelHtml", "app.channel_html_export.write.app_error", nil, err.Error(), http.StatusInternalServerError)
+}
+
+func (e *channelHtmlExporter) writePosts() *model.AppError {
+	afterCreateAt, afterId := int64(0), ""
+	for {
+		result := <-e.app.Srv.Store.Post().GetPostsForChannelExport(e.channel.Id, afterCreateAt, afterId, CHANNEL_HTML_EXPORT_BATCH_SIZE)
+		if result.Err != nil {
+			return result.Err
+		}
+
+		posts := result.Data.([]*model.Post)
+		for _, post := range posts {
+			if err := e.writePost(post); err != nil {
+				return err
+			}
+		}
+
+		if len(posts) < CHANNEL_HTML_EXPORT_BATCH_SIZE {
+			return nil
+		}
+
+		last := posts[len(posts)-1]
+		afterCreateAt, afterId = last.CreateAt, last.Id
+	}
+}
+
+func (e *channelHtmlExporter) writePost(post *model.Post) *model.AppError {
+	if month := model.ChannelHtmlExportMonthName(post.CreateAt, e.location); e.month == nil || e.month.Month != month {
+		if err := e.closeMonth(); err != nil {
+			return err
+		}
+
+		if err := e.openMonth(month, post.CreateAt); err != nil {
+			return err
+		}
+	}
+
+	createAt := e.time(post.CreateAt)
+	view := &channelHtmlExportPost{
+		Id:       post.Id,
+		Username: post.UserId,
+		IsSystem: post.IsSystemMessage(),
+		IsEdited: post.EditAt > 0,
+		DateTime: createAt.Format(time.RFC3339),
+		Time:     createAt.Format("Jan 2, 2006 15:04"),
+		RootHref: e.rootHref(post.RootId),
+	}
+
+	// Deactivated users are kept in the store along with their usernames, so they're shown as they were last known
+	if user := e.getUser(post.UserId); user != nil {
+		view.Username = user.Username
+		view.FullName = user.GetFullName()
+		view.IsDeactivated = user.DeleteAt > 0
+	}
+
+	if overrideUsername, ok := post.Props["override_username"].(string); ok && overrideUsername != "" && post.Props["from_webhook"] == "true" {
+		view.Username = overrideUsername
+		view.FullName = ""
+	}
+
+	if view.IsSystem {
+		view.Message = template.HTML(template.HTMLEscapeString(post.Message))
+	} else {
+		view.Message = template.HTML(markdown.RenderHTMLWithText(post.Message, e.renderText))
+	}
+
+	for _, fileId := range post.FileIds {
+		view.Files = append(view.Files, e.copyFile(fileId))
+	}
+
+	if err := channelHtmlExportTemplates.ExecuteTemplate(e.writer, "post", view); err != nil {
+		return newChannelHtmlExportWriteError(err)
+	}
+
+	e.addParticipant(post, view)
+	e.month.PostCount++
+	e.export.PostCount++
+
+	return nil
+}
+
+func (e *channelHtmlExporter) openMonth(month string, at int64) *model.AppError {
+	e.month = &model.ChannelHtmlExportMonth{
+		Month:    month,
+		FileName: model.ChannelHtmlExportMonthFileName(month),
+	}
+	e.export.Months = append(e.export.Months, e.month)
+
+	file, err := os.Create(filepath.Join(e.dir, e.month.FileName))
+	if err != nil {
+		return newChannelHtmlExportWriteError(err)
+	}
+	e.monthFile = file
+	e.writer = bufio.NewWriter(file)
+
+	monthLabel := e.time(at).Format("January 2006")
+	if err := channelHtmlExportTemplates.ExecuteTemplate(e.writer, "month_header", &channelHtmlExportPage{
+		Title:       e.channelName + " - " + monthLabel,
+		ChannelName: e.channelName,
+		MonthLabel:  monthLabel,
+	}); err != nil {
+		return newChannelHtmlExportWriteError(err)
+	}
+
+	return nil
+}
+
+func (e *channelHtmlExporter) closeMonth() *model.AppError {
+	if e.monthFile == nil {
+		return nil
+	}
+
+	file := e.monthFile
+	e.monthFile = nil
+
+	err := channelHtmlExportTemplates.ExecuteTemplate(e.writer, "month_footer", &channelHtmlExportPage{ChannelName: e.channelName})
+	if err == nil {
+		err = e.writer.Flush()
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return newChannelHtmlExportWriteError(err)
+	}
+
+	return nil
+}
+
+func (e *channelHtmlExporter) writeIndex() *model.AppError {
+	for _, participant := range e.participants {
+		e.export.Participants = append(e.export.Participants, participant)
+	}
+	sort.Slice(e.export.Participants, func(i, j int) bool {
+		return e.export.Participants[i].Username < e.export.Participants[j].Username
+	})
+
+	index := &channelHtmlExportIndex{
+		channelHtmlExportPage: channelHtmlExportPage{
+			Title:       e.channelName,
+			ChannelName: e.channelName,
+		},
+		Purpose:    template.HTML(markdown.RenderHTMLWithText(e.channel.Purpose, e.renderText)),
+		Header:     template.HTML(markdown.RenderHTMLWithText(e.channel.Header, e.renderText)),
+		Months:     e.export.Months,
+		PostCount:  e.export.PostCount,
+		FileCount:  e.export.FileCount,
+		ExportedAt: e.time(model.GetMillis()).Format("Jan 2, 2006 15:04"),
+	}
+
+	if e.channel.TeamId != "" {
+		if team, err := e.app.GetTeam(e.channel.TeamId); err != nil {
+			mlog.Warn(fmt.Sprintf("Unable to get the team of an exported channel, err=%v", err), mlog.String("channel_id", e.channel.Id))
+		} else {
+			index.TeamName = team.DisplayName
+		}
+	}
+
+	for _, participant := range e.export.Participants {
+		index.Participants = append(index.Participants, &channelHtmlExportIndexParticipant{
+			ChannelHtmlExportParticipant: participant,
+			FirstPost:                    e.time(participant.FirstPostAt).Format("Jan 2, 2006"),
+			LastPost:                     e.time(participant.LastPostAt).Format("Jan 2, 2006"),
+		})
+	}
+
+	file, err := os.Create(filepath.Join(e.dir, model.CHANNEL_HTML_EXPORT_INDEX_FILE))
+	if err != nil {
+		return newChannelHtmlExportWriteError(err)
+	}
+
+	writer := bufio.NewWriter(file)
+	err = channelHtmlExportTemplates.ExecuteTemplate(writer, "index", index)
+	if err == nil {
+		err = writer.Flush()
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return newChannelHtmlExportWriteError(err)
+	}
+
+	return nil
+}
+
+func (e *channelHtmlExporter) time(millis int64) time.Time {
+	return time.Unix(0, millis*int64(time.Millisecond)).In(e.location)
+}
+
+func (e *channelHtmlExporter) addParticipant(post *model.Post, view *channelHtmlExportPost) {
+	participant, ok := e.participants[post.UserId]
+	if !ok {
+		participant = &model.ChannelHtmlExportParticipant{
+			UserId:        post.UserId,
+			Username:      post.UserId,
+			IsDeactivated: view.IsDeactivated,
+			FirstPostAt:   post.CreateAt,
+		}
+
+		if user := e.getUser(post.UserId); user != nil {
+			participant.Username = user.Username
+			participant.FullName = user.GetFullName()
+		}
+
+		e.participants[post.UserId] = participant
+	}
+
+	participant.PostCount++
+	participant.LastPostAt = post.CreateAt
+}
+
+// getUser returns the user with the given id, or nil if they can't be found.
+func (e *channelHtmlExporter) getUser(userId string) *model.User {
+	if user, ok := e.users[userId]; ok {
+		return user
+	}
+
+	var user *model.User
+	if result := <-e.app.Srv.Store.User().Get(userId); result.Err == nil {
+		user = result.Data.(*model.User)
+	}
+
+	e.users[userId] = user
+	return user
+}
+
+// getUserByUsername returns the user with the given username, or nil if there isn't one.
+func (e *channelHtmlExporter) getUserByUsername(username string) *model.User {
+	if user, ok := e.usernames[username]; ok {
+		return user
+	}
+
+	var user *model.User
+	if result := <-e.app.Srv.Store.User().GetByUsername(username); result.Err == nil {
+		user = result.Data.(*model.User)
+	}
+
+	e.usernames[username] = user
+	return user
+}
+
+// rootHref returns a link to the root of the thread that a reply is in, which may be on the page for an earlier
+// month. Replies usually follow each other, so the last root is remembered.
+func (e *channelHtmlExporter) rootHref(rootId string) string {
+	if rootId == "" {
+		return ""
+	} else if rootId == e.lastRootId {
+		return e.lastRootHref
+	}
+
+	e.lastRootId = rootId
+	e.lastRootHref = ""
+	if result := <-e.app.Srv.Store.Post().GetSingle(rootId); result.Err == nil {
+		root := result.Data.(*model.Post)
+		if root.DeleteAt == 0 {
+			e.lastRootHref = model.ChannelHtmlExportMonthFileName(model.ChannelHtmlExportMonthName(root.CreateAt, e.location)) + "#post-" + root.Id
+		}
+	}
+
+	return e.lastRootHref
+}
+
+// renderText produces the HTML for a run of plain text in a message, highlighting the mentions of users that exist
+// and replacing the names of emoji with the emoji themselves.
+func (e *channelHtmlExporter) renderText(text string) string {
+	result := ""
+	position := 0
+	for _, match := range channelHtmlExportTextPattern.FindAllStringIndex(text, -1) {
+		word := text[match[0]:match[1]]
+
+		var rendered string
+		if word[0] == '@' {
+			rendered = e.renderMention(word)
+		} else {
+			rendered = e.renderEmoji(word[1 : len(word)-1])
+		}
+
+		if rendered != "" {
+			result += template.HTMLEscapeString(text[position:match[0]]) + rendered
+			position = match[1]
+		}
+	}
+
+	return result + template.HTMLEscapeString(text[position:])
+}
+
+func (e *channelHtmlExporter) renderMention(mention string) string {
+	username := strings.ToLower(mention[1:])
+
+	// Mentions can be followed by punctuation that's also allowed in usernames, so it's trimmed until one is found
+	for username != "" {
+		trailing := template.HTMLEscapeString(mention[1+len(username):])
+
+		if username == "channel" || username == "all" || username == "here" {
+			return `<span class="mention mention--special">@` + username + `</span>` + trailing
+		}
+
+		if user := e.getUserByUsername(username); user != nil {
+			return `<span class="mention" title="` + template.HTMLEscapeString(user.GetFullName()) + `">@` + template.HTMLEscapeString(user.Username) + `</span>` + trailing
+		}
+
+		if last := username[len(username)-1]; last != '.' && last != '-' && last != '_' {
+			break
+		}
+		username = username[:len(username)-1]
+	}
+
+	return ""
+}
+
+// renderEmoji returns the HTML for a system or custom emoji, copying the images of custom emoji into the archive.
+func (e *channelHtmlExporter) renderEmoji(name string) string {
+	if rendered, ok := e.emojis[name]; ok {
+		return rendered
+	}
+
+	rendered := ""
+	if code, ok := model.SystemEmojis[name]; ok {
+		if emoji := emojiFromCodePoints(code); emoji != "" {
+			rendered = `<span class="emoji" title=":` + name + `:">` + emoji + `</span>`
+		}
+	} else if *e.app.Config().ServiceSettings.EnableCustomEmoji {
+		rendered = e.copyCustomEmoji(name)
+	}
+
+	e.emojis[name] = rendered
+	return rendered
+}
+
+// emojiFromCodePoints converts the code points of a system emoji, such as 1f44d or 1f468-200d-1f4bb, into the
+// emoji itself.
+func emojiFromCodePoints(code string) string {
+	emoji := ""
+	for _, part := range strings.Split(code, "-") {
+		codePoint, err := strconv.ParseInt(part, 16, 32)
+		if err != nil {
+			return ""
+		}
+		emoji += string(rune(codePoint))
+	}
+
+	return emoji
+}
+
+func (e *channelHtmlExporter) copyCustomEmoji(name string) string {
+	emoji, err := e.app.GetEmojiByName(name)
+	if err != nil {
+		return ""
+	}
+
+	data, imageType, err := e.app.GetEmojiImage(emoji.Id)
+	if err != nil {
+		mlog.Warn(fmt.Sprintf("Unable to read the image of an exported emoji, err=%v", err), mlog.String("emoji_id", emoji.Id))
+		return ""
+	}
+
+	href := model.CHANNEL_HTML_EXPORT_EMOJI_DIR + "/" + emoji.Id + "." + imageType
+	if err := e.writeArchiveFile(href, data); err != nil {
+		mlog.Warn(fmt.Sprintf("Unable to write the image of an exported emoji, err=%v", err), mlog.String("emoji_id", emoji.Id))
+		return ""
+	}
+
+	return `<img class="emoji" src="` + href + `" alt=":` + name + `:" title=":` + name + `:">`
+}
+
+// copyFile copies a file attached to a post into the archive along with its thumbnail, if it has one. Files that
+// can't be found are noted as missing instead of failing the export.
+func (e *channelHtmlExporter) copyFile(fileId string) *channelHtmlExportFile {
+	result := <-e.app.Srv.Store.FileInfo().Get(fileId)
+	if result.Err != nil {
+		e.export.MissingFileCount++
+		return &channelHtmlExportFile{Name: fileId, IsMissing: true}
+	}
+	info := result.Data.(*model.FileInfo)
+
+	file := &channelHtmlExportFile{Name: info.Name}
+
+	name := filepath.Base(info.Name)
+	if name == "." || name == ".." || name == string(filepath.Separator) {
+		name = info.Id + "." + info.Extension
+	}
+	dir := model.CHANNEL_HTML_EXPORT_FILES_DIR + "/" + info.Id
+
+	data, err := e.app.ReadFile(info.Path)
+	if err == nil {
+		file.Href = dir + "/" + name
+		err = e.writeArchiveFile(file.Href, data)
+	}
+	if err != nil {
+		mlog.Warn(fmt.Sprintf("Unable to copy an exported file, err=%v", err), mlog.String("file_id", info.Id))
+		e.export.MissingFileCount++
+		file.IsMissing = true
+		return file
+	}
+
+	if info.ThumbnailPath != "" {
+		if data, err := e.app.ReadFile(info.ThumbnailPath); err != nil {
+			mlog.Warn(fmt.Sprintf("Unable to read the thumbnail of an exported file, err=%v", err), mlog.String("file_id", info.Id))
+		} else if err := e.writeArchiveFile(dir+"/thumbnail.jpg", data); err != nil {
+			mlog.Warn(fmt.Sprintf("Unable to write the thumbnail of an exported file, err=%v", err), mlog.String("file_id", info.Id))
+		} else {
+			file.ThumbnailHref = dir + "/thumbnail.jpg"
+		}
+	}
+
+	e.export.FileCount++
+	return file
+}
+
+// writeArchiveFile writes data to a path relative to the root of the archive.
+func (e *channelHtmlExporter) writeArchiveFile(path string, data []byte) *model.AppError {
+	fullPath := filepath.Join(e.dir, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0750); err != nil {
+		return newChannelHtmlExportWriteError(err)
+	}
+
+	if err := ioutil.WriteFile(fullPath, data, 0640); err != nil {
+		return newChannelHtmlExportWriteError(err)
+	}
+
+	return nil
+}
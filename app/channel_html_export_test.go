@@ -0,0 +1,140 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestExportChannelHtml(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	channel := th.CreateChannel(th.BasicTeam)
+	january := time.Date(2018, time.January, 15, 12, 0, 0, 0, time.UTC).UnixNano() / int64(time.Millisecond)
+	february := time.Date(2018, time.February, 3, 9, 30, 0, 0, time.UTC).UnixNano() / int64(time.Millisecond)
+
+	savePost := func(post *model.Post) *model.Post {
+		post.ChannelId = channel.Id
+		result := <-th.App.Srv.Store.Post().Save(post)
+		require.Nil(t, result.Err)
+		return result.Data.(*model.Post)
+	}
+
+	_, err := th.App.WriteFile(bytes.NewReader([]byte("attached")), "exported/report.txt")
+	require.Nil(t, err)
+	_, err = th.App.WriteFile(bytes.NewReader([]byte("thumbnail")), "exported/photo_thumb.jpg")
+	require.Nil(t, err)
+	_, err = th.App.WriteFile(bytes.NewReader([]byte("photo")), "exported/photo.jpg")
+	require.Nil(t, err)
+
+	report := &model.FileInfo{CreatorId: th.BasicUser.Id, Name: "report.txt", Extension: "txt", Path: "exported/report.txt"}
+	photo := &model.FileInfo{CreatorId: th.BasicUser.Id, Name: "photo.jpg", Extension: "jpg", Path: "exported/photo.jpg", ThumbnailPath: "exported/photo_thumb.jpg"}
+	for _, info := range []*model.FileInfo{report, photo} {
+		result := <-th.App.Srv.Store.FileInfo().Save(info)
+		require.Nil(t, result.Err)
+	}
+	missingFileId := model.NewId()
+
+	root := savePost(&model.Post{
+		UserId:   th.BasicUser.Id,
+		Message:  "hello @" + th.BasicUser2.Username + ", see **attached** :tada: <script> [x](javascript:alert(1)) [docs](https://docs.mattermost.com)",
+		CreateAt: january,
+		FileIds:  model.StringArray{report.Id, photo.Id, missingFileId},
+	})
+
+	deactivated := th.CreateUser()
+	th.LinkUserToTeam(deactivated, th.BasicTeam)
+	th.AddUserToChannel(deactivated, channel)
+	reply := savePost(&model.Post{
+		UserId:   deactivated.Id,
+		RootId:   root.Id,
+		ParentId: root.Id,
+		Message:  "a reply to @nobody-here",
+		CreateAt: february,
+	})
+	_, err = th.App.UpdateActive(deactivated, false)
+	require.Nil(t, err)
+
+	deleted := savePost(&model.Post{UserId: th.BasicUser.Id, Message: "deleted", CreateAt: february + 1})
+	_, err = th.App.DeletePost(deleted.Id)
+	require.Nil(t, err)
+
+	dir, ioErr := ioutil.TempDir("", "html_export")
+	require.NoError(t, ioErr)
+	defer os.RemoveAll(dir)
+
+	export, err := th.App.ExportChannelHtml(channel, dir, time.UTC)
+	require.Nil(t, err)
+
+	assert.Equal(t, 2, export.PostCount, "deleted posts shouldn't be exported")
+	assert.Equal(t, 2, export.FileCount)
+	assert.Equal(t, 1, export.MissingFileCount)
+	require.Len(t, export.Months, 2)
+	assert.Equal(t, &model.ChannelHtmlExportMonth{Month: "2018-01", FileName: "2018-01.html", PostCount: 1}, export.Months[0])
+	assert.Equal(t, &model.ChannelHtmlExportMonth{Month: "2018-02", FileName: "2018-02.html", PostCount: 1}, export.Months[1])
+	require.Len(t, export.Participants, 2)
+
+	readFile := func(name string) string {
+		data, ioErr := ioutil.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, ioErr)
+		return string(data)
+	}
+
+	t.Run("index", func(t *testing.T) {
+		index := readFile("index.html")
+		assert.Contains(t, index, "<h1>"+channel.DisplayName+"</h1>")
+		assert.Contains(t, index, `<a href="2018-01.html">2018-01</a> (1 posts)`)
+		assert.Contains(t, index, `<a href="2018-02.html">2018-02</a> (1 posts)`)
+		assert.Contains(t, index, `id="user-`+th.BasicUser.Id+`"><td>`+th.BasicUser.Username+`</td>`)
+		assert.Contains(t, index, `id="user-`+deactivated.Id+`"><td>`+deactivated.Username+` (deactivated)</td>`)
+	})
+
+	t.Run("posts", func(t *testing.T) {
+		page := readFile("2018-01.html")
+		assert.Contains(t, page, `<article class="post" id="post-`+root.Id+`">`)
+		assert.Contains(t, page, `<span class="mention" title="`+th.BasicUser2.GetFullName()+`">@`+th.BasicUser2.Username+`</span>`)
+		assert.Contains(t, page, `<span class="emoji" title=":tada:">`+"\U0001f389"+`</span>`)
+		assert.Contains(t, page, "&lt;script&gt;")
+		assert.NotContains(t, page, "<script>")
+		assert.Contains(t, page, `<a href="https://docs.mattermost.com">docs</a>`)
+		assert.NotContains(t, page, "javascript:", "links with unsafe URLs shouldn't be exported")
+		assert.NotContains(t, page, reply.Id)
+
+		page = readFile("2018-02.html")
+		assert.Contains(t, page, `id="post-`+reply.Id+`"`)
+		assert.Contains(t, page, `<a class="post__root" href="2018-01.html#post-`+root.Id+`">`)
+		assert.Contains(t, page, `post__user--deactivated`)
+		assert.Contains(t, page, deactivated.Username)
+		assert.Contains(t, page, "a reply to @nobody-here", "unknown users aren't rendered as mentions")
+		assert.NotContains(t, page, deleted.Id)
+	})
+
+	t.Run("attachments", func(t *testing.T) {
+		page := readFile("2018-01.html")
+		assert.Contains(t, page, `<a href="files/`+report.Id+`/report.txt">report.txt</a>`)
+		assert.Contains(t, page, `<a href="files/`+photo.Id+`/photo.jpg"><img src="files/`+photo.Id+`/thumbnail.jpg" alt="photo.jpg"><br>photo.jpg</a>`)
+		assert.Contains(t, page, `<span class="file--missing">`+missingFileId+`</span>`)
+
+		assert.Equal(t, "attached", readFile(filepath.Join("files", report.Id, "report.txt")))
+		assert.Equal(t, "photo", readFile(filepath.Join("files", photo.Id, "photo.jpg")))
+		assert.Equal(t, "thumbnail", readFile(filepath.Join("files", photo.Id, "thumbnail.jpg")))
+	})
+}
+
+func TestEmojiFromCodePoints(t *testing.T) {
+	assert.Equal(t, "\U0001f44d", emojiFromCodePoints("1f44d"))
+	assert.Equal(t, "\U0001f468\u200d\U0001f4bb", emojiFromCodePoints("1f468-200d-1f4bb"))
+	assert.Equal(t, "", emojiFromCodePoints("not-hex"))
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/disintegration/imaging"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// channelIconFormats are the image formats that can be uploaded as channel icons.
+var channelIconFormats = map[string]bool{
+	"png":  true,
+	"jpeg": true,
+	"gif":  true,
+}
+
+func getChannelIconPath(channelId string) string {
+	return "channels/" + channelId + "/icon.png"
+}
+
+func (a *App) GetChannelIcon(channel *model.Channel) ([]byte, *model.AppError) {
+	if len(*a.Config().FileSettings.DriverName) == 0 {
+		return nil, model.NewAppError("GetChannelIcon", "api.channel.get_channel_icon.filesettings_no_driver.app_error", nil, "", http.StatusNotImplemented)
+	}
+
+	if channel.LastIconUpdate == 0 {
+		return nil, model.NewAppError("GetChannelIcon", "api.channel.get_channel_icon.not_found.app_error", nil, "channel_id="+channel.Id, http.StatusNotFound)
+	}
+
+	data, err := a.ReadFile(getChannelIconPath(channel.Id))
+	if err != nil {
+		return nil, model.NewAppError("GetChannelIcon", "api.channel.get_channel_icon.read_file.app_error", nil, err.Error(), http.StatusNotFound)
+	}
+
+	return data, nil
+}
+
+func (a *App) SetChannelIcon(channelId string, imageData *multipart.FileHeader) *model.AppError {
+	file, err := imageData.Open()
+	if err != nil {
+		return model.NewAppError("SetChannelIcon", "api.channel.set_channel_icon.open.app_error", nil, err.Error(), http.StatusBadRequest)
+	}
+	defer file.Close()
+
+	return a.SetChannelIconFromFile(channelId, file)
+}
+
+// SetChannelIconFromFile scales the image down to a square icon for the channel, replacing its emoji if it has one.
+func (a *App) SetChannelIconFromFile(channelId string, file io.ReadSeeker) *model.AppError {
+	channel, err := a.GetChannel(channelId)
+	if err != nil {
+		return err
+	}
+
+	if channel.IsGroupOrDirect() {
+		return model.NewAppError("SetChannelIcon", "api.channel.set_channel_icon.direct.app_error", nil, "channel_id="+channelId, http.StatusBadRequest)
+	}
+
+	if len(*a.Config().FileSettings.DriverName) == 0 {
+		return model.NewAppError("SetChannelIcon", "api.channel.set_channel_icon.storage.app_error", nil, "", http.StatusNotImplemented)
+	}
+
+	// Decode image config first to check the type and dimensions before loading the whole thing into memory
+	config, format, decodeErr := image.DecodeConfig(file)
+	if decodeErr != nil {
+		return model.NewAppError("SetChannelIcon", "api.channel.set_channel_icon.decode_config.app_error", nil, decodeErr.Error(), http.StatusBadRequest)
+	} else if !channelIconFormats[format] {
+		return model.NewAppError("SetChannelIcon", "api.channel.set_channel_icon.format.app_error", map[string]interface{}{"Format": format}, "", http.StatusBadRequest)
+	} else if config.Width*config.Height > model.MaxImageSize {
+		return model.NewAppError("SetChannelIcon", "api.channel.set_channel_icon.too_large.app_error", nil, fmt.Sprintf("width=%v, height=%v", config.Width, config.Height), http.StatusBadRequest)
+	}
+
+	file.Seek(0, 0)
+
+	img, _, decodeErr := image.Decode(file)
+	if decodeErr != nil {
+		return model.NewAppError("SetChannelIcon", "api.channel.set_channel_icon.decode.app_error", nil, decodeErr.Error(), http.StatusBadRequest)
+	}
+
+	file.Seek(0, 0)
+
+	orientation, _ := getImageOrientation(file)
+	img = makeImageUpright(img, orientation)
+	img = imaging.Fill(img, model.CHANNEL_ICON_SIZE, model.CHANNEL_ICON_SIZE, imaging.Center, imaging.Lanczos)
+
+	buf := new(bytes.Buffer)
+	if encodeErr := png.Encode(buf, img); encodeErr != nil {
+		return model.NewAppError("SetChannelIcon", "api.channel.set_channel_icon.encode.app_error", nil, encodeErr.Error(), http.StatusInternalServerError)
+	}
+
+	if _, err := a.WriteFile(buf, getChannelIconPath(channelId)); err != nil {
+		return model.NewAppError("SetChannelIcon", "api.channel.set_channel_icon.write_file.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	channel.IconEmojiName = ""
+	channel.LastIconUpdate = model.GetMillis()
+
+	_, err = a.UpdateChannel(channel)
+	return err
+}
+
+// RemoveChannelIcon stops the channel from showing the icon that was uploaded for it.
+func (a *App) RemoveChannelIcon(channelId string) *model.AppError {
+	channel, err := a.GetChannel(channelId)
+	if err != nil {
+		return err
+	}
+
+	channel.LastIconUpdate = 0
+
+	_, err = a.UpdateChannel(channel)
+	return err
+}
+
+// checkChannelIconEmoji returns an error if the channel's icon has been changed from old's to a custom emoji that
+// doesn't exist.
+func (a *App) checkChannelIconEmoji(channel *model.Channel, old *model.Channel) *model.AppError {
+	if channel.IconEmojiName == "" || channel.IconEmojiName == old.IconEmojiName {
+		return nil
+	}
+
+	if _, ok := model.SystemEmojis[channel.IconEmojiName]; ok {
+		return nil
+	}
+
+	if _, err := a.GetEmojiByName(channel.IconEmojiName); err != nil {
+		return model.NewAppError("checkChannelIconEmoji", "app.channel.icon_emoji_not_found.app_error", map[string]interface{}{"Name": channel.IconEmojiName}, err.Error(), http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+// clearChannelIconsForEmoji removes the deleted custom emoji with the given name from the channels that use it as
+// their icon, so that they're shown with the default icon instead of a broken image.
+func (a *App) clearChannelIconsForEmoji(emojiName string) {
+	result := <-a.Srv.Store.Channel().GetByIconEmojiName(emojiName)
+	if result.Err != nil {
+		mlog.Warn(fmt.Sprintf("Unable to clear channel icons when deleting emoji with emoji name %v err=%v", emojiName, result.Err.Error()))
+		return
+	}
+
+	for _, channel := range result.Data.([]*model.Channel) {
+		channel.IconEmojiName = ""
+		if _, err := a.UpdateChannel(channel); err != nil {
+			mlog.Warn(fmt.Sprintf("Unable to clear the icon of channel %v when deleting emoji with emoji name %v err=%v", channel.Id, emojiName, err.Error()))
+		}
+	}
+}
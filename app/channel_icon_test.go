@@ -0,0 +1,153 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/bmp"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+// createTestPngWithSize returns a PNG whose header claims that it's width by height without the pixels to match.
+func createTestPngWithSize(t *testing.T, width uint32, height uint32) []byte {
+	data := utils.CreateTestPng(t, 1, 1)
+
+	// The IHDR chunk comes straight after the signature, with its length and type before the dimensions
+	binary.BigEndian.PutUint32(data[16:20], width)
+	binary.BigEndian.PutUint32(data[20:24], height)
+	binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+
+	return data
+}
+
+func TestSetChannelIcon(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	channel := th.BasicChannel
+
+	getChannel := func(t *testing.T) *model.Channel {
+		channel, err := th.App.GetChannel(channel.Id)
+		require.Nil(t, err)
+		return channel
+	}
+
+	for name, data := range map[string][]byte{
+		"png":  utils.CreateTestPng(t, 100, 50),
+		"jpeg": utils.CreateTestJpeg(t, 50, 100),
+		"gif":  utils.CreateTestGif(t, 10, 10),
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.Nil(t, th.App.SetChannelIconFromFile(channel.Id, bytes.NewReader(data)))
+
+			updated := getChannel(t)
+			assert.NotZero(t, updated.LastIconUpdate)
+
+			icon, err := th.App.GetChannelIcon(updated)
+			require.Nil(t, err)
+
+			config, format, decodeErr := image.DecodeConfig(bytes.NewReader(icon))
+			require.Nil(t, decodeErr)
+			assert.Equal(t, "png", format)
+			assert.Equal(t, model.CHANNEL_ICON_SIZE, config.Width)
+			assert.Equal(t, model.CHANNEL_ICON_SIZE, config.Height)
+		})
+	}
+
+	t.Run("not an image", func(t *testing.T) {
+		err := th.App.SetChannelIconFromFile(channel.Id, bytes.NewReader([]byte("not an image")))
+		require.NotNil(t, err)
+		assert.Equal(t, "api.channel.set_channel_icon.decode_config.app_error", err.Id)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+		img.Set(0, 0, color.White)
+
+		data := &bytes.Buffer{}
+		require.Nil(t, bmp.Encode(data, img))
+
+		err := th.App.SetChannelIconFromFile(channel.Id, bytes.NewReader(data.Bytes()))
+		require.NotNil(t, err)
+		assert.Equal(t, "api.channel.set_channel_icon.format.app_error", err.Id)
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		err := th.App.SetChannelIconFromFile(channel.Id, bytes.NewReader(createTestPngWithSize(t, 10000, 10000)))
+		require.NotNil(t, err)
+		assert.Equal(t, "api.channel.set_channel_icon.too_large.app_error", err.Id)
+	})
+
+	t.Run("direct channel", func(t *testing.T) {
+		direct := th.CreateDmChannel(th.BasicUser2)
+
+		err := th.App.SetChannelIconFromFile(direct.Id, bytes.NewReader(utils.CreateTestPng(t, 10, 10)))
+		require.NotNil(t, err)
+		assert.Equal(t, "api.channel.set_channel_icon.direct.app_error", err.Id)
+	})
+
+	t.Run("replaces the emoji", func(t *testing.T) {
+		_, err := th.App.PatchChannel(getChannel(t), &model.ChannelPatch{IconEmojiName: model.NewString("smile")}, th.BasicUser.Id)
+		require.Nil(t, err)
+		assert.Zero(t, getChannel(t).LastIconUpdate, "setting an emoji should replace the uploaded icon")
+
+		require.Nil(t, th.App.SetChannelIconFromFile(channel.Id, bytes.NewReader(utils.CreateTestPng(t, 10, 10))))
+		assert.Empty(t, getChannel(t).IconEmojiName)
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		require.Nil(t, th.App.RemoveChannelIcon(channel.Id))
+
+		updated := getChannel(t)
+		assert.Zero(t, updated.LastIconUpdate)
+
+		_, err := th.App.GetChannelIcon(updated)
+		require.NotNil(t, err)
+		assert.Equal(t, "api.channel.get_channel_icon.not_found.app_error", err.Id)
+	})
+}
+
+func TestChannelIconEmoji(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.ServiceSettings.EnableCustomEmoji = true
+	})
+
+	setIconEmoji := func(emojiName string) *model.AppError {
+		channel, err := th.App.GetChannel(th.BasicChannel.Id)
+		require.Nil(t, err)
+
+		_, err = th.App.PatchChannel(channel, &model.ChannelPatch{IconEmojiName: model.NewString(emojiName)}, th.BasicUser.Id)
+		return err
+	}
+
+	require.Nil(t, setIconEmoji("smile"), "system emojis can be used")
+
+	err := setIconEmoji("missing" + model.NewId())
+	require.NotNil(t, err)
+	assert.Equal(t, "app.channel.icon_emoji_not_found.app_error", err.Id)
+
+	emoji := store.Must(th.App.Srv.Store.Emoji().Save(&model.Emoji{CreatorId: th.BasicUser.Id, Name: "emoji" + model.NewId()})).(*model.Emoji)
+	require.Nil(t, setIconEmoji(emoji.Name))
+
+	// The channel falls back to having no icon when its emoji is deleted
+	require.Nil(t, th.App.DeleteEmoji(emoji))
+
+	channel, err := th.App.GetChannel(th.BasicChannel.Id)
+	require.Nil(t, err)
+	assert.Empty(t, channel.IconEmojiName)
+}
@@ -0,0 +1,179 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// GetChannelModerationsForChannel returns the state of every moderated permission in a channel.
+func (a *App) GetChannelModerationsForChannel(channel *model.Channel) ([]*model.ChannelModeration, *model.AppError) {
+	if channel.Type == model.CHANNEL_DIRECT || channel.Type == model.CHANNEL_GROUP {
+		return nil, model.NewAppError("GetChannelModerationsForChannel", "app.channel.moderations.channel_type.app_error", nil, "channel_id="+channel.Id, http.StatusBadRequest)
+	}
+
+	moderated, err := a.getModeratedPermissionsForChannel(channel.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildChannelModerations(moderated), nil
+}
+
+// PatchChannelModerationsForChannel updates which moderated permissions are available to the guests and the
+// regular members of a channel. Permissions not mentioned in the patches are left unchanged.
+func (a *App) PatchChannelModerationsForChannel(channel *model.Channel, patches []*model.ChannelModerationPatch) ([]*model.ChannelModeration, *model.AppError) {
+	if channel.Type == model.CHANNEL_DIRECT || channel.Type == model.CHANNEL_GROUP {
+		return nil, model.NewAppError("PatchChannelModerationsForChannel", "app.channel.moderations.channel_type.app_error", nil, "channel_id="+channel.Id, http.StatusBadRequest)
+	}
+
+	current, err := a.getModeratedPermissionsForChannel(channel.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	deniedGuests := make(map[string]bool)
+	for _, name := range current.Guests {
+		deniedGuests[name] = true
+	}
+	deniedMembers := make(map[string]bool)
+	for _, name := range current.Members {
+		deniedMembers[name] = true
+	}
+
+	for _, patch := range patches {
+		if patch == nil || patch.Name == nil || !model.IsValidChannelModeratedPermission(*patch.Name) {
+			return nil, model.NewAppError("PatchChannelModerationsForChannel", "app.channel.patch_channel_moderations.name.app_error", nil, "channel_id="+channel.Id, http.StatusBadRequest)
+		}
+
+		if patch.Roles == nil {
+			continue
+		}
+
+		if patch.Roles.Guests != nil {
+			if !channelModeratedPermissionAppliesToGuests(*patch.Name) {
+				// Guests never have the permission, so it can only be left taken away from them
+				if *patch.Roles.Guests {
+					return nil, model.NewAppError("PatchChannelModerationsForChannel", "app.channel.patch_channel_moderations.guests.app_error", nil, "channel_id="+channel.Id+", name="+*patch.Name, http.StatusBadRequest)
+				}
+			} else if *patch.Roles.Guests {
+				delete(deniedGuests, *patch.Name)
+			} else {
+				deniedGuests[*patch.Name] = true
+			}
+		}
+
+		if patch.Roles.Members != nil {
+			if *patch.Roles.Members {
+				delete(deniedMembers, *patch.Name)
+			} else {
+				deniedMembers[*patch.Name] = true
+			}
+		}
+	}
+
+	moderated := &model.ChannelModeratedPermissions{Guests: []string{}, Members: []string{}}
+	for _, name := range model.CHANNEL_MODERATED_PERMISSIONS {
+		if deniedGuests[name] {
+			moderated.Guests = append(moderated.Guests, name)
+		}
+		if deniedMembers[name] {
+			moderated.Members = append(moderated.Members, name)
+		}
+	}
+
+	if result := <-a.Srv.Store.Channel().UpdateModeratedPermissions(channel.Id, moderated); result.Err != nil {
+		return nil, result.Err
+	}
+
+	return buildChannelModerations(moderated), nil
+}
+
+// IsChannelModerated returns true if the given moderated permission has been taken away from the guests of a
+// channel, if guests is true, or from its regular members otherwise.
+func (a *App) IsChannelModerated(channelId string, name string, guests bool) bool {
+	moderated, err := a.getModeratedPermissionsForChannel(channelId)
+	if err != nil {
+		// Like RolesGrantPermission, deny rather than let a broken lookup hand out permissions.
+		mlog.Error(fmt.Sprintf("Failed to get channel moderations, channel_id=%v err=%v", channelId, err.Error()))
+		return true
+	}
+
+	return moderated.IsModerated(name, guests)
+}
+
+// sessionIsModeratedInChannel returns true if the channel's moderation settings withhold the named
+// moderated permission from the session's user. Users who can manage the channel's roles are exempt.
+func (a *App) sessionIsModeratedInChannel(session model.Session, channelId string, name string) bool {
+	if name == "" || !a.IsChannelModerated(channelId, name, session.IsGuest()) {
+		return false
+	}
+
+	return !a.SessionHasPermissionToChannel(session, channelId, model.PERMISSION_MANAGE_CHANNEL_ROLES)
+}
+
+// userIsModeratedInChannel returns true if the channel's moderation settings withhold the named
+// moderated permission from the user. Users who can manage the channel's roles are exempt.
+func (a *App) userIsModeratedInChannel(userId string, channelId string, name string) bool {
+	if name == "" {
+		return false
+	}
+
+	moderatedForGuests := a.IsChannelModerated(channelId, name, true)
+	moderatedForMembers := a.IsChannelModerated(channelId, name, false)
+	if !moderatedForGuests && !moderatedForMembers {
+		return false
+	}
+
+	// The user only needs looking up when the permission is withheld from one of the roles, and is left moderated
+	// if they can't be
+	if moderatedForGuests != moderatedForMembers {
+		if user, err := a.GetUser(userId); err == nil {
+			if user.IsGuest() && !moderatedForGuests || !user.IsGuest() && !moderatedForMembers {
+				return false
+			}
+		}
+	}
+
+	return !a.HasPermissionToChannel(userId, channelId, model.PERMISSION_MANAGE_CHANNEL_ROLES)
+}
+
+func (a *App) getModeratedPermissionsForChannel(channelId string) (*model.ChannelModeratedPermissions, *model.AppError) {
+	result := <-a.Srv.Store.Channel().GetModeratedPermissions(channelId)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.ChannelModeratedPermissions), nil
+}
+
+// channelModeratedPermissionAppliesToGuests returns false for the moderated permissions that the channel_guest role
+// never grants, since there's nothing to take away from guests.
+func channelModeratedPermissionAppliesToGuests(name string) bool {
+	return name != model.CHANNEL_MODERATED_PERMISSION_MANAGE_MEMBERS
+}
+
+func buildChannelModerations(moderated *model.ChannelModeratedPermissions) []*model.ChannelModeration {
+	moderations := make([]*model.ChannelModeration, 0, len(model.CHANNEL_MODERATED_PERMISSIONS))
+	for _, name := range model.CHANNEL_MODERATED_PERMISSIONS {
+		guests := &model.ChannelModeratedRole{Value: false, Enabled: false}
+		if channelModeratedPermissionAppliesToGuests(name) {
+			guests = &model.ChannelModeratedRole{Value: !moderated.IsModerated(name, true), Enabled: true}
+		}
+
+		moderations = append(moderations, &model.ChannelModeration{
+			Name: name,
+			Roles: &model.ChannelModeratedRoles{
+				Guests:  guests,
+				Members: &model.ChannelModeratedRole{Value: !moderated.IsModerated(name, false), Enabled: true},
+			},
+		})
+	}
+
+	return moderations
+}
@@ -0,0 +1,223 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func moderateChannelForTest(t *testing.T, th *TestHelper, channel *model.Channel, name string, members bool) {
+	_, err := th.App.PatchChannelModerationsForChannel(channel, []*model.ChannelModerationPatch{
+		{Name: model.NewString(name), Roles: &model.ChannelModeratedRolesPatch{Members: model.NewBool(members)}},
+	})
+	require.Nil(t, err)
+}
+
+func TestPatchChannelModerationsForChannel(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	moderations, err := th.App.GetChannelModerationsForChannel(th.BasicChannel)
+	require.Nil(t, err)
+	require.Len(t, moderations, len(model.CHANNEL_MODERATED_PERMISSIONS))
+	for _, moderation := range moderations {
+		assert.True(t, moderation.Roles.Members.Value)
+		assert.True(t, moderation.Roles.Members.Enabled)
+
+		// Guests can't manage members, so that permission can't be given to them
+		canGuests := moderation.Name != model.CHANNEL_MODERATED_PERMISSION_MANAGE_MEMBERS
+		assert.Equal(t, canGuests, moderation.Roles.Guests.Value, moderation.Name)
+		assert.Equal(t, canGuests, moderation.Roles.Guests.Enabled, moderation.Name)
+	}
+
+	moderations, err = th.App.PatchChannelModerationsForChannel(th.BasicChannel, []*model.ChannelModerationPatch{
+		{Name: model.NewString(model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS), Roles: &model.ChannelModeratedRolesPatch{Members: model.NewBool(false)}},
+		{Name: model.NewString(model.CHANNEL_MODERATED_PERMISSION_MANAGE_MEMBERS), Roles: &model.ChannelModeratedRolesPatch{Members: model.NewBool(false)}},
+	})
+	require.Nil(t, err)
+	for _, moderation := range moderations {
+		expected := moderation.Name != model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS && moderation.Name != model.CHANNEL_MODERATED_PERMISSION_MANAGE_MEMBERS
+		assert.Equal(t, expected, moderation.Roles.Members.Value, moderation.Name)
+	}
+
+	t.Run("guests are moderated separately", func(t *testing.T) {
+		moderations, err := th.App.PatchChannelModerationsForChannel(th.BasicChannel, []*model.ChannelModerationPatch{
+			{Name: model.NewString(model.CHANNEL_MODERATED_PERMISSION_CREATE_REACTIONS), Roles: &model.ChannelModeratedRolesPatch{Guests: model.NewBool(false)}},
+		})
+		require.Nil(t, err)
+		for _, moderation := range moderations {
+			if moderation.Name == model.CHANNEL_MODERATED_PERMISSION_CREATE_REACTIONS {
+				assert.False(t, moderation.Roles.Guests.Value)
+				assert.True(t, moderation.Roles.Members.Value)
+			} else if moderation.Name == model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS {
+				assert.True(t, moderation.Roles.Guests.Value)
+				assert.False(t, moderation.Roles.Members.Value)
+			}
+		}
+
+		assert.True(t, th.App.IsChannelModerated(th.BasicChannel.Id, model.CHANNEL_MODERATED_PERMISSION_CREATE_REACTIONS, true))
+		assert.False(t, th.App.IsChannelModerated(th.BasicChannel.Id, model.CHANNEL_MODERATED_PERMISSION_CREATE_REACTIONS, false))
+
+		_, err = th.App.PatchChannelModerationsForChannel(th.BasicChannel, []*model.ChannelModerationPatch{
+			{Name: model.NewString(model.CHANNEL_MODERATED_PERMISSION_CREATE_REACTIONS), Roles: &model.ChannelModeratedRolesPatch{Guests: model.NewBool(true)}},
+		})
+		require.Nil(t, err)
+		assert.False(t, th.App.IsChannelModerated(th.BasicChannel.Id, model.CHANNEL_MODERATED_PERMISSION_CREATE_REACTIONS, true))
+	})
+
+	t.Run("unmentioned permissions are left alone", func(t *testing.T) {
+		moderateChannelForTest(t, th, th.BasicChannel, model.CHANNEL_MODERATED_PERMISSION_MANAGE_MEMBERS, true)
+
+		assert.True(t, th.App.IsChannelModerated(th.BasicChannel.Id, model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS, false))
+		assert.False(t, th.App.IsChannelModerated(th.BasicChannel.Id, model.CHANNEL_MODERATED_PERMISSION_MANAGE_MEMBERS, false))
+	})
+
+	t.Run("invalid patches", func(t *testing.T) {
+		_, err := th.App.PatchChannelModerationsForChannel(th.BasicChannel, []*model.ChannelModerationPatch{
+			{Name: model.NewString("junk"), Roles: &model.ChannelModeratedRolesPatch{Members: model.NewBool(false)}},
+		})
+		require.NotNil(t, err)
+		assert.Equal(t, "app.channel.patch_channel_moderations.name.app_error", err.Id)
+
+		_, err = th.App.PatchChannelModerationsForChannel(th.BasicChannel, []*model.ChannelModerationPatch{
+			{Name: model.NewString(model.CHANNEL_MODERATED_PERMISSION_MANAGE_MEMBERS), Roles: &model.ChannelModeratedRolesPatch{Guests: model.NewBool(true)}},
+		})
+		require.NotNil(t, err)
+		assert.Equal(t, "app.channel.patch_channel_moderations.guests.app_error", err.Id)
+	})
+
+	t.Run("direct channels can't be moderated", func(t *testing.T) {
+		dm, err := th.App.CreateDirectChannel(th.BasicUser.Id, th.BasicUser2.Id)
+		require.Nil(t, err)
+
+		_, err = th.App.GetChannelModerationsForChannel(dm)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.channel.moderations.channel_type.app_error", err.Id)
+	})
+
+	t.Run("town square can be moderated", func(t *testing.T) {
+		townSquare, err := th.App.GetChannelByName(model.DEFAULT_CHANNEL, th.BasicTeam.Id)
+		require.Nil(t, err)
+
+		moderateChannelForTest(t, th, townSquare, model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS, false)
+		assert.True(t, th.App.IsChannelModerated(townSquare.Id, model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS, false))
+		assert.False(t, th.App.HasPermissionToChannel(th.BasicUser2.Id, townSquare.Id, model.PERMISSION_CREATE_POST))
+	})
+}
+
+func TestChannelModerationPermissions(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+
+	// BasicUser created BasicChannel and is its admin, BasicUser2 is a regular member
+	th.AddUserToChannel(th.BasicUser2, th.BasicChannel)
+
+	for _, tc := range []struct {
+		Name       string
+		Permission *model.Permission
+	}{
+		{model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS, model.PERMISSION_CREATE_POST},
+		{model.CHANNEL_MODERATED_PERMISSION_CREATE_REACTIONS, model.PERMISSION_ADD_REACTION},
+		{model.CHANNEL_MODERATED_PERMISSION_CREATE_REACTIONS, model.PERMISSION_REMOVE_REACTION},
+		{model.CHANNEL_MODERATED_PERMISSION_MANAGE_MEMBERS, model.PERMISSION_MANAGE_PUBLIC_CHANNEL_MEMBERS},
+	} {
+		t.Run(tc.Permission.Id, func(t *testing.T) {
+			assert.True(t, th.App.HasPermissionToChannel(th.BasicUser2.Id, th.BasicChannel.Id, tc.Permission))
+
+			moderateChannelForTest(t, th, th.BasicChannel, tc.Name, false)
+			defer moderateChannelForTest(t, th, th.BasicChannel, tc.Name, true)
+
+			session := model.Session{UserId: th.BasicUser2.Id, Roles: model.SYSTEM_USER_ROLE_ID}
+			assert.False(t, th.App.HasPermissionToChannel(th.BasicUser2.Id, th.BasicChannel.Id, tc.Permission))
+			assert.False(t, th.App.SessionHasPermissionToChannel(session, th.BasicChannel.Id, tc.Permission))
+			assert.False(t, th.App.HasPermissionToChannelByPost(th.BasicUser2.Id, th.BasicPost.Id, tc.Permission))
+			assert.False(t, th.App.SessionHasPermissionToChannelByPost(session, th.BasicPost.Id, tc.Permission))
+
+			// Channel and system admins aren't affected by moderation
+			assert.True(t, th.App.HasPermissionToChannel(th.BasicUser.Id, th.BasicChannel.Id, tc.Permission))
+			adminSession := model.Session{UserId: th.SystemAdminUser.Id, Roles: model.SYSTEM_USER_ROLE_ID + " " + model.SYSTEM_ADMIN_ROLE_ID}
+			assert.True(t, th.App.SessionHasPermissionToChannel(adminSession, th.BasicChannel.Id, tc.Permission))
+
+			// Other channels keep their permissions
+			other := th.CreateChannel(th.BasicTeam)
+			th.AddUserToChannel(th.BasicUser2, other)
+			assert.True(t, th.App.HasPermissionToChannel(th.BasicUser2.Id, other.Id, tc.Permission))
+		})
+	}
+
+	t.Run("guests", func(t *testing.T) {
+		guest, err := th.App.CreateGuest(&model.User{Email: th.MakeEmail(), Username: "un_" + model.NewId(), Password: "Password1"})
+		require.Nil(t, err)
+		th.LinkUserToTeam(guest, th.BasicTeam)
+		th.AddUserToChannel(guest, th.BasicChannel)
+		guestSession := model.Session{UserId: guest.Id, Roles: model.SYSTEM_GUEST_ROLE_ID}
+		assert.True(t, th.App.HasPermissionToChannel(guest.Id, th.BasicChannel.Id, model.PERMISSION_CREATE_POST))
+
+		// Taking a permission away from members leaves guests alone
+		moderateChannelForTest(t, th, th.BasicChannel, model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS, false)
+		assert.True(t, th.App.HasPermissionToChannel(guest.Id, th.BasicChannel.Id, model.PERMISSION_CREATE_POST))
+		assert.True(t, th.App.SessionHasPermissionToChannel(guestSession, th.BasicChannel.Id, model.PERMISSION_CREATE_POST))
+		moderateChannelForTest(t, th, th.BasicChannel, model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS, true)
+
+		// And the other way around
+		_, err = th.App.PatchChannelModerationsForChannel(th.BasicChannel, []*model.ChannelModerationPatch{
+			{Name: model.NewString(model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS), Roles: &model.ChannelModeratedRolesPatch{Guests: model.NewBool(false)}},
+		})
+		require.Nil(t, err)
+		defer th.App.PatchChannelModerationsForChannel(th.BasicChannel, []*model.ChannelModerationPatch{
+			{Name: model.NewString(model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS), Roles: &model.ChannelModeratedRolesPatch{Guests: model.NewBool(true)}},
+		})
+
+		assert.False(t, th.App.HasPermissionToChannel(guest.Id, th.BasicChannel.Id, model.PERMISSION_CREATE_POST))
+		assert.False(t, th.App.SessionHasPermissionToChannel(guestSession, th.BasicChannel.Id, model.PERMISSION_CREATE_POST))
+		assert.True(t, th.App.HasPermissionToChannel(th.BasicUser2.Id, th.BasicChannel.Id, model.PERMISSION_CREATE_POST))
+	})
+
+	t.Run("promoted members are exempt", func(t *testing.T) {
+		moderateChannelForTest(t, th, th.BasicChannel, model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS, false)
+		defer moderateChannelForTest(t, th, th.BasicChannel, model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS, true)
+
+		_, err := th.App.UpdateChannelMemberRoles(th.BasicChannel.Id, th.BasicUser2.Id, model.CHANNEL_USER_ROLE_ID+" "+model.CHANNEL_ADMIN_ROLE_ID)
+		require.Nil(t, err)
+		assert.True(t, th.App.HasPermissionToChannel(th.BasicUser2.Id, th.BasicChannel.Id, model.PERMISSION_CREATE_POST))
+
+		_, err = th.App.UpdateChannelMemberRoles(th.BasicChannel.Id, th.BasicUser2.Id, model.CHANNEL_USER_ROLE_ID)
+		require.Nil(t, err)
+		assert.False(t, th.App.HasPermissionToChannel(th.BasicUser2.Id, th.BasicChannel.Id, model.PERMISSION_CREATE_POST))
+
+		_, err = th.App.UpdateTeamMemberRoles(th.BasicTeam.Id, th.BasicUser2.Id, model.TEAM_USER_ROLE_ID+" "+model.TEAM_ADMIN_ROLE_ID)
+		require.Nil(t, err)
+		assert.True(t, th.App.HasPermissionToChannel(th.BasicUser2.Id, th.BasicChannel.Id, model.PERMISSION_CREATE_POST))
+	})
+}
+
+func TestChannelModerationOfChannelMentions(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.AddUserToChannel(th.BasicUser2, th.BasicChannel)
+
+	post := &model.Post{UserId: th.BasicUser2.Id, ChannelId: th.BasicChannel.Id, Message: "@channel announcement", CreateAt: model.GetMillis()}
+
+	mentions, err := th.App.SendNotifications(post, th.BasicTeam, th.BasicChannel, th.BasicUser2, nil)
+	require.Nil(t, err)
+	assert.Contains(t, mentions, th.BasicUser.Id)
+
+	moderateChannelForTest(t, th, th.BasicChannel, model.CHANNEL_MODERATED_PERMISSION_USE_CHANNEL_MENTIONS, false)
+
+	mentions, err = th.App.SendNotifications(post, th.BasicTeam, th.BasicChannel, th.BasicUser2, nil)
+	require.Nil(t, err)
+	assert.NotContains(t, mentions, th.BasicUser.Id)
+
+	// The channel admin can still notify everyone
+	post = &model.Post{UserId: th.BasicUser.Id, ChannelId: th.BasicChannel.Id, Message: "@all announcement", CreateAt: model.GetMillis()}
+	mentions, err = th.App.SendNotifications(post, th.BasicTeam, th.BasicChannel, th.BasicUser, nil)
+	require.Nil(t, err)
+	assert.Contains(t, mentions, th.BasicUser2.Id)
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// updateChannelNameHistory records a channel's old name after it's been renamed so that it can still be found by
+// it. The new name is removed from the history since it now belongs to the channel.
+func (a *App) updateChannelNameHistory(channel *model.Channel, oldName string) {
+	if channel.TeamId == "" || channel.Name == oldName {
+		return
+	}
+
+	history := &model.ChannelNameHistory{
+		TeamId:    channel.TeamId,
+		Name:      oldName,
+		ChannelId: channel.Id,
+	}
+	if result := <-a.Srv.Store.Channel().SaveNameHistory(history, model.CHANNEL_NAME_HISTORY_MAX_PER_CHANNEL); result.Err != nil {
+		mlog.Warn(fmt.Sprintf("Failed to save the old name of a channel: %v", result.Err.Error()), mlog.String("channel_id", channel.Id))
+	}
+
+	a.removeChannelNameFromHistory(channel)
+}
+
+// removeChannelNameFromHistory stops the name of a channel from redirecting to another channel that used to have it.
+func (a *App) removeChannelNameFromHistory(channel *model.Channel) {
+	if channel.TeamId == "" {
+		return
+	}
+
+	if result := <-a.Srv.Store.Channel().DeleteNameHistory(channel.TeamId, channel.Name); result.Err != nil {
+		mlog.Warn(fmt.Sprintf("Failed to remove a channel's name from the history of old names: %v", result.Err.Error()), mlog.String("channel_id", channel.Id))
+	}
+}
+
+// getChannelByOldName returns the channel that used to be called channelName in a team, with RedirectedFrom set to
+// that name. notFound is returned if there's no such channel or it's been deleted.
+func (a *App) getChannelByOldName(teamId string, channelName string, notFound *model.AppError) (*model.Channel, *model.AppError) {
+	result := <-a.Srv.Store.Channel().GetNameHistoryByName(teamId, channelName)
+	if result.Err != nil {
+		return nil, notFound
+	}
+	history := result.Data.(*model.ChannelNameHistory)
+
+	channel, err := a.GetChannel(history.ChannelId)
+	if err != nil || channel.DeleteAt > 0 {
+		return nil, notFound
+	}
+
+	// The channel may be shared with the cache
+	channel = channel.DeepCopy()
+	channel.RedirectedFrom = channelName
+
+	return channel, nil
+}
+
+// GetChannelNameHistory returns the old names that still redirect to a channel, newest first.
+func (a *App) GetChannelNameHistory(channelId string) ([]*model.ChannelNameHistory, *model.AppError) {
+	result := <-a.Srv.Store.Channel().GetNameHistory(channelId)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.([]*model.ChannelNameHistory), nil
+}
+
+// DeleteChannelNameHistory stops one of a channel's old names from redirecting to it.
+func (a *App) DeleteChannelNameHistory(channel *model.Channel, name string) *model.AppError {
+	result := <-a.Srv.Store.Channel().GetNameHistoryByName(channel.TeamId, name)
+	if result.Err != nil {
+		return result.Err
+	}
+
+	if result.Data.(*model.ChannelNameHistory).ChannelId != channel.Id {
+		return model.NewAppError("DeleteChannelNameHistory", "app.channel.delete_name_history.not_found.app_error", nil, "channel_id="+channel.Id+", name="+name, http.StatusNotFound)
+	}
+
+	if result := <-a.Srv.Store.Channel().DeleteNameHistory(channel.TeamId, name); result.Err != nil {
+		return result.Err
+	}
+
+	a.InvalidateCacheForChannelByNameSkipClusterSend(channel.TeamId, name)
+
+	return nil
+}
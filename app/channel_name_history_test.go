@@ -0,0 +1,123 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func renameChannelForTest(t *testing.T, th *TestHelper, channel *model.Channel, name string) *model.Channel {
+	channel, err := th.App.PatchChannel(channel, &model.ChannelPatch{Name: model.NewString(name)}, th.BasicUser.Id)
+	require.Nil(t, err)
+	return channel
+}
+
+func TestGetChannelByOldName(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	channel := th.CreateChannel(th.BasicTeam)
+	firstName := channel.Name
+	secondName := "second-" + model.NewId()
+	thirdName := "third-" + model.NewId()
+
+	// Looked up by its first name so that it's in the cache
+	_, err := th.App.GetChannelByName(firstName, th.BasicTeam.Id)
+	require.Nil(t, err)
+
+	channel = renameChannelForTest(t, th, channel, secondName)
+	channel = renameChannelForTest(t, th, channel, thirdName)
+
+	t.Run("current name", func(t *testing.T) {
+		found, err := th.App.GetChannelByName(thirdName, th.BasicTeam.Id)
+		require.Nil(t, err)
+		assert.Equal(t, channel.Id, found.Id)
+		assert.Equal(t, "", found.RedirectedFrom)
+	})
+
+	t.Run("old names", func(t *testing.T) {
+		for _, name := range []string{firstName, secondName} {
+			found, err := th.App.GetChannelByName(name, th.BasicTeam.Id)
+			require.Nil(t, err, name)
+			assert.Equal(t, channel.Id, found.Id)
+			assert.Equal(t, thirdName, found.Name)
+			assert.Equal(t, name, found.RedirectedFrom)
+
+			found, err = th.App.GetChannelByNameForTeamName(name, th.BasicTeam.Name)
+			require.Nil(t, err, name)
+			assert.Equal(t, channel.Id, found.Id)
+			assert.Equal(t, name, found.RedirectedFrom)
+		}
+
+		history, err := th.App.GetChannelNameHistory(channel.Id)
+		require.Nil(t, err)
+		require.Len(t, history, 2)
+		assert.Equal(t, secondName, history[0].Name)
+		assert.Equal(t, firstName, history[1].Name)
+	})
+
+	t.Run("other team", func(t *testing.T) {
+		_, err := th.App.GetChannelByName(secondName, model.NewId())
+		require.NotNil(t, err)
+		assert.Equal(t, "store.sql_channel.get_by_name.missing.app_error", err.Id)
+	})
+
+	t.Run("reused by a new channel", func(t *testing.T) {
+		reused, err := th.App.CreateChannel(&model.Channel{
+			TeamId:      th.BasicTeam.Id,
+			DisplayName: "Reused",
+			Name:        secondName,
+			Type:        model.CHANNEL_OPEN,
+			CreatorId:   th.BasicUser.Id,
+		}, true)
+		require.Nil(t, err)
+
+		found, err := th.App.GetChannelByName(secondName, th.BasicTeam.Id)
+		require.Nil(t, err)
+		assert.Equal(t, reused.Id, found.Id)
+		assert.Equal(t, "", found.RedirectedFrom)
+
+		history, err := th.App.GetChannelNameHistory(channel.Id)
+		require.Nil(t, err)
+		require.Len(t, history, 1)
+		assert.Equal(t, firstName, history[0].Name)
+	})
+
+	t.Run("renamed back", func(t *testing.T) {
+		channel = renameChannelForTest(t, th, channel, firstName)
+
+		found, err := th.App.GetChannelByName(thirdName, th.BasicTeam.Id)
+		require.Nil(t, err)
+		assert.Equal(t, channel.Id, found.Id)
+		assert.Equal(t, thirdName, found.RedirectedFrom)
+
+		history, err := th.App.GetChannelNameHistory(channel.Id)
+		require.Nil(t, err)
+		require.Len(t, history, 1)
+		assert.Equal(t, thirdName, history[0].Name)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		err := th.App.DeleteChannelNameHistory(th.BasicChannel, thirdName)
+		require.NotNil(t, err)
+
+		require.Nil(t, th.App.DeleteChannelNameHistory(channel, thirdName))
+
+		_, err = th.App.GetChannelByName(thirdName, th.BasicTeam.Id)
+		require.NotNil(t, err)
+	})
+
+	t.Run("deleted channel", func(t *testing.T) {
+		channel = renameChannelForTest(t, th, channel, secondName+"-archived")
+		require.Nil(t, th.App.DeleteChannel(channel, th.BasicUser.Id))
+
+		_, err := th.App.GetChannelByName(firstName, th.BasicTeam.Id)
+		require.NotNil(t, err)
+	})
+}
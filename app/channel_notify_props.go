@@ -0,0 +1,83 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// defaultChannelNotifyProps returns the notify props that members of public and private channels start with, which
+// are TeamSettings.DefaultChannelNotifyProps with any that it leaves out filled in as usual. Direct and group channels
+// don't use them since everything posted in those is meant for their members.
+func (a *App) defaultChannelNotifyProps() model.StringMap {
+	props := model.GetDefaultChannelNotifyProps()
+	for name, value := range a.Config().TeamSettings.DefaultChannelNotifyProps {
+		props[name] = value
+	}
+
+	return props
+}
+
+// UpdateChannelMembersNotifyProps changes the user's notify props for each of the given channels at once, leaving
+// any that aren't given for a channel as they are. If any of the props aren't valid or the user isn't a member of
+// one of the channels, nothing is changed.
+func (a *App) UpdateChannelMembersNotifyProps(userId string, propsByChannel model.ChannelNotifyPropsByChannel) ([]*model.ChannelMember, *model.AppError) {
+	if len(propsByChannel) > model.CHANNEL_NOTIFY_PROPS_BULK_MAX_CHANNELS {
+		return nil, model.NewAppError("UpdateChannelMembersNotifyProps", "app.channel.notify_props.too_many.app_error", map[string]interface{}{"Max": model.CHANNEL_NOTIFY_PROPS_BULK_MAX_CHANNELS}, "", http.StatusBadRequest)
+	}
+
+	// The channels are gone through in order so that the same error is returned for the same request every time
+	channelIds := make([]string, 0, len(propsByChannel))
+	for channelId := range propsByChannel {
+		channelIds = append(channelIds, channelId)
+	}
+	sort.Strings(channelIds)
+
+	members := make([]*model.ChannelMember, 0, len(channelIds))
+	for _, channelId := range channelIds {
+		props := propsByChannel[channelId]
+		if err := model.IsChannelNotifyPropsValid(props); err != nil {
+			err.DetailedError += ", channel_id=" + channelId
+			return nil, err
+		}
+
+		member, err := a.GetChannelMember(channelId, userId)
+		if err != nil {
+			return nil, err
+		}
+
+		for name, value := range props {
+			member.NotifyProps[name] = value
+		}
+		members = append(members, member)
+	}
+
+	if len(members) == 0 {
+		return members, nil
+	}
+
+	if result := <-a.Srv.Store.Channel().UpdateMembers(members); result.Err != nil {
+		return nil, result.Err
+	}
+
+	a.InvalidateCacheForUser(userId)
+	for _, member := range members {
+		a.InvalidateCacheForChannelMembersNotifyProps(member.ChannelId)
+	}
+
+	// The user's clients are sent every changed membership in one event rather than one for each channel
+	updated := make(model.ChannelMembers, 0, len(members))
+	for _, member := range members {
+		updated = append(updated, *member)
+	}
+
+	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_CHANNEL_MEMBERS_UPDATED, "", "", userId, nil)
+	message.Add("channelMembers", updated.ToJson())
+	a.Publish(message)
+
+	return members, nil
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestDefaultChannelNotifyProps(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		cfg.TeamSettings.DefaultChannelNotifyProps = map[string]string{
+			model.DESKTOP_NOTIFY_PROP: model.CHANNEL_NOTIFY_MENTION,
+			model.PUSH_NOTIFY_PROP:    model.CHANNEL_NOTIFY_NONE,
+		}
+	})
+
+	user := th.CreateUser()
+	_, err := th.App.AddTeamMember(th.BasicTeam.Id, user.Id)
+	require.Nil(t, err)
+
+	t.Run("joining the team", func(t *testing.T) {
+		townSquare, err := th.App.GetChannelByName(model.DEFAULT_CHANNEL, th.BasicTeam.Id)
+		require.Nil(t, err)
+
+		member, err := th.App.GetChannelMember(townSquare.Id, user.Id)
+		require.Nil(t, err)
+		assert.Equal(t, model.CHANNEL_NOTIFY_MENTION, member.NotifyProps[model.DESKTOP_NOTIFY_PROP])
+		assert.Equal(t, model.CHANNEL_NOTIFY_NONE, member.NotifyProps[model.PUSH_NOTIFY_PROP])
+		assert.Equal(t, model.CHANNEL_MARK_UNREAD_ALL, member.NotifyProps[model.MARK_UNREAD_NOTIFY_PROP], "props left out of the template should have their usual defaults")
+	})
+
+	t.Run("joining a channel", func(t *testing.T) {
+		member, err := th.App.AddUserToChannel(user, th.BasicChannel)
+		require.Nil(t, err)
+		assert.Equal(t, model.CHANNEL_NOTIFY_MENTION, member.NotifyProps[model.DESKTOP_NOTIFY_PROP])
+		assert.Equal(t, model.CHANNEL_NOTIFY_NONE, member.NotifyProps[model.PUSH_NOTIFY_PROP])
+	})
+
+	t.Run("group channels keep the usual defaults", func(t *testing.T) {
+		channel, err := th.App.CreateGroupChannel([]string{th.BasicUser.Id, th.BasicUser2.Id, user.Id}, th.BasicUser.Id)
+		require.Nil(t, err)
+
+		member, err := th.App.GetChannelMember(channel.Id, user.Id)
+		require.Nil(t, err)
+		assert.Equal(t, model.CHANNEL_NOTIFY_DEFAULT, member.NotifyProps[model.DESKTOP_NOTIFY_PROP])
+	})
+}
+
+func TestUpdateChannelMembersNotifyProps(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	other := th.CreateChannel(th.BasicTeam)
+
+	members, err := th.App.UpdateChannelMembersNotifyProps(th.BasicUser.Id, model.ChannelNotifyPropsByChannel{
+		th.BasicChannel.Id: {model.EMAIL_NOTIFY_PROP: "false"},
+		other.Id:           {model.EMAIL_NOTIFY_PROP: "true"},
+	})
+	require.Nil(t, err)
+	assert.Len(t, members, 2)
+
+	member, err := th.App.GetChannelMember(other.Id, th.BasicUser.Id)
+	require.Nil(t, err)
+	assert.Equal(t, "true", member.NotifyProps[model.EMAIL_NOTIFY_PROP])
+
+	propsByChannel := model.ChannelNotifyPropsByChannel{}
+	for i := 0; i <= model.CHANNEL_NOTIFY_PROPS_BULK_MAX_CHANNELS; i++ {
+		propsByChannel[model.NewId()] = model.StringMap{}
+	}
+	_, err = th.App.UpdateChannelMembersNotifyProps(th.BasicUser.Id, propsByChannel)
+	require.NotNil(t, err)
+	assert.Equal(t, "app.channel.notify_props.too_many.app_error", err.Id)
+}
@@ -4,11 +4,15 @@
 package app
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/store"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPermanentDeleteChannel(t *testing.T) {
@@ -381,3 +385,228 @@ func TestAddChannelMemberNoUserRequestor(t *testing.T) {
 		assert.Equal(t, user.Username, post.Props["username"])
 	}
 }
+
+func TestChannelMemberHistoryRecordsActors(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	channel := th.createChannel(th.BasicTeam, model.CHANNEL_OPEN)
+
+	// BasicUser2 joins by themselves and is then removed by BasicUser
+	if err := th.App.JoinChannel(channel, th.BasicUser2.Id); err != nil {
+		t.Fatal("Failed to join channel. Error: " + err.Message)
+	}
+	if err := th.App.RemoveUserFromChannel(th.BasicUser2.Id, th.BasicUser.Id, channel); err != nil {
+		t.Fatal("Failed to remove user from channel. Error: " + err.Message)
+	}
+
+	histories, err := th.App.GetChannelMemberHistory(channel.Id, 0, 0, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, histories, 2) {
+		assert.Equal(t, th.BasicUser2.Id, histories[0].UserId)
+		assert.Equal(t, th.BasicUser2.Id, histories[0].JoinActorId)
+		assert.Equal(t, th.BasicUser.Id, histories[0].LeaveActorId)
+		assert.Equal(t, model.CHANNEL_MEMBER_LEAVE_REASON_REMOVED, histories[0].LeaveReason)
+
+		assert.Equal(t, th.BasicUser.Id, histories[1].UserId)
+		assert.Equal(t, th.BasicUser.Id, histories[1].JoinActorId)
+	}
+
+	// a user added by another user, who later leaves by themselves
+	user := th.CreateUser()
+	th.LinkUserToTeam(user, th.BasicTeam)
+	if _, err := th.App.AddChannelMember(user.Id, channel, th.BasicUser.Id, ""); err != nil {
+		t.Fatal("Failed to add user to channel. Error: " + err.Message)
+	}
+	if err := th.App.LeaveChannel(channel.Id, user.Id); err != nil {
+		t.Fatal("Failed to leave channel. Error: " + err.Message)
+	}
+
+	// and one added by the system
+	user2 := th.CreateUser()
+	th.LinkUserToTeam(user2, th.BasicTeam)
+	if _, err := th.App.AddUserToChannel(user2, channel); err != nil {
+		t.Fatal("Failed to add user to channel. Error: " + err.Message)
+	}
+
+	histories = store.Must(th.App.Srv.Store.ChannelMemberHistory().GetUsersInChannelDuring(0, model.GetMillis()+100, channel.Id)).([]*model.ChannelMemberHistoryResult)
+	byUser := map[string]*model.ChannelMemberHistoryResult{}
+	for _, history := range histories {
+		byUser[history.UserId] = history
+	}
+
+	if assert.NotNil(t, byUser[user.Id]) {
+		assert.Equal(t, th.BasicUser.Id, byUser[user.Id].JoinActorId)
+		assert.Equal(t, user.Id, byUser[user.Id].LeaveActorId)
+		assert.Equal(t, model.CHANNEL_MEMBER_LEAVE_REASON_LEFT, byUser[user.Id].LeaveReason)
+	}
+
+	if assert.NotNil(t, byUser[user2.Id]) {
+		assert.Equal(t, model.CHANNEL_MEMBER_HISTORY_ACTOR_SYSTEM, byUser[user2.Id].JoinActorId)
+		assert.Nil(t, byUser[user2.Id].LeaveTime)
+	}
+}
+
+func TestJoinLeaveMessagesSetting(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	enabledByDefault := *th.App.Config().ServiceSettings.EnableJoinLeaveMessagesByDefault
+	defer th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableJoinLeaveMessagesByDefault = enabledByDefault })
+
+	user := th.CreateUser()
+	th.LinkUserToTeam(user, th.BasicTeam)
+
+	countPosts := func(channel *model.Channel) int {
+		postList := store.Must(th.App.Srv.Store.Post().GetPosts(channel.Id, 0, 100, false)).(*model.PostList)
+		return len(postList.Order)
+	}
+
+	for _, tc := range []struct {
+		Name              string
+		EnabledByDefault  bool
+		JoinLeaveMessages string
+		Expected          bool
+	}{
+		{"server default enabled", true, model.CHANNEL_JOIN_LEAVE_MESSAGES_DEFAULT, true},
+		{"server default disabled", false, model.CHANNEL_JOIN_LEAVE_MESSAGES_DEFAULT, false},
+		{"channel enabled", false, model.CHANNEL_JOIN_LEAVE_MESSAGES_ON, true},
+		{"channel disabled", true, model.CHANNEL_JOIN_LEAVE_MESSAGES_OFF, false},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableJoinLeaveMessagesByDefault = tc.EnabledByDefault })
+
+			channel := th.createChannel(th.BasicTeam, model.CHANNEL_OPEN)
+			channel.JoinLeaveMessages = tc.JoinLeaveMessages
+			channel, err := th.App.UpdateChannel(channel)
+			require.Nil(t, err)
+
+			assert.Equal(t, tc.Expected, th.App.IsJoinLeaveMessageEnabled(channel))
+
+			before := countPosts(channel)
+			require.Nil(t, th.App.PostAddToChannelMessage(th.BasicUser, user, channel, ""))
+			require.Nil(t, th.App.postRemoveFromChannelMessage(th.BasicUser.Id, user, channel))
+
+			if tc.Expected {
+				assert.Equal(t, before+2, countPosts(channel))
+			} else {
+				assert.Equal(t, before, countPosts(channel))
+			}
+		})
+	}
+}
+
+func TestHiddenJoinLeaveMessagesAreNotUnread(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	channel := th.createChannel(th.BasicTeam, model.CHANNEL_OPEN)
+	th.AddUserToChannel(th.BasicUser2, channel)
+
+	require.Nil(t, th.App.UpdatePreferences(th.BasicUser2.Id, model.Preferences{{
+		UserId:   th.BasicUser2.Id,
+		Category: model.PREFERENCE_CATEGORY_ADVANCED_SETTINGS,
+		Name:     model.PREFERENCE_NAME_JOIN_LEAVE,
+		Value:    "false",
+	}}))
+
+	_, err := th.App.ViewChannel(&model.ChannelView{ChannelId: channel.Id}, th.BasicUser.Id, false)
+	require.Nil(t, err)
+	_, err = th.App.ViewChannel(&model.ChannelView{ChannelId: channel.Id}, th.BasicUser2.Id, false)
+	require.Nil(t, err)
+
+	user := th.CreateUser()
+	require.Nil(t, th.App.postAddToTeamMessage(th.BasicUser, user, channel, ""))
+
+	channel = store.Must(th.App.Srv.Store.Channel().Get(channel.Id, false)).(*model.Channel)
+
+	member, err := th.App.GetChannelMember(channel.Id, th.BasicUser.Id)
+	require.Nil(t, err)
+	assert.Equal(t, channel.TotalMsgCount-1, member.MsgCount, "the message should be unread for a user showing join/leave messages")
+
+	member, err = th.App.GetChannelMember(channel.Id, th.BasicUser2.Id)
+	require.Nil(t, err)
+	assert.Equal(t, channel.TotalMsgCount, member.MsgCount, "the message shouldn't be unread for a user hiding join/leave messages")
+}
+
+func TestMarkChannelsAsViewed(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	var lock sync.Mutex
+	var cleared []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if msg := model.PushNotificationFromJson(r.Body); msg != nil && msg.Type == model.PUSH_TYPE_CLEAR {
+			lock.Lock()
+			cleared = append(cleared, msg.ChannelId)
+			lock.Unlock()
+		}
+		w.Write([]byte(model.MapToJson(map[string]string{model.PUSH_STATUS: model.PUSH_STATUS_OK})))
+	}))
+	defer ts.Close()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.EmailSettings.SendPushNotifications = true
+		*cfg.EmailSettings.PushNotificationServer = ts.URL
+	})
+
+	_, err := th.App.CreateSession(&model.Session{
+		UserId:   th.BasicUser.Id,
+		DeviceId: "android:" + model.NewId(),
+	})
+	require.Nil(t, err)
+
+	require.Nil(t, th.App.UpdatePreferences(th.BasicUser.Id, model.Preferences{{
+		UserId:   th.BasicUser.Id,
+		Category: model.PREFERENCE_CATEGORY_DISPLAY_SETTINGS,
+		Name:     model.PREFERENCE_NAME_COLLAPSED_THREADS,
+		Value:    model.COLLAPSED_THREADS_ON,
+	}}))
+
+	th.AddUserToChannel(th.BasicUser2, th.BasicChannel)
+	dm := th.CreateDmChannel(th.BasicUser2)
+
+	th.createReply(th.BasicUser2, th.BasicPost, "@"+th.BasicUser.Username+" in a thread")
+	_, err = th.App.CreatePost(&model.Post{UserId: th.BasicUser2.Id, ChannelId: dm.Id, Message: "direct message"}, dm, false)
+	require.Nil(t, err)
+
+	// Let the push notifications for the posts be sent before counting the ones that clear them
+	th.App.WaitForGoroutines()
+	lock.Lock()
+	cleared = nil
+	lock.Unlock()
+
+	thread, err := th.App.GetThreadForUser(th.BasicUser.Id, th.BasicTeam.Id, th.BasicPost.Id)
+	require.Nil(t, err)
+	require.Equal(t, int64(1), thread.UnreadMentions)
+
+	channelIds, err := th.App.MarkChannelsAsViewed(th.BasicUser.Id, th.BasicTeam.Id, true)
+	require.Nil(t, err)
+	assert.ElementsMatch(t, []string{th.BasicChannel.Id, dm.Id}, channelIds)
+
+	for _, channelId := range channelIds {
+		member, err := th.App.GetChannelMember(channelId, th.BasicUser.Id)
+		require.Nil(t, err)
+		assert.Equal(t, int64(0), member.MentionCount)
+
+		channel, err := th.App.GetChannel(channelId)
+		require.Nil(t, err)
+		assert.Equal(t, channel.TotalMsgCount, member.MsgCount)
+	}
+
+	thread, err = th.App.GetThreadForUser(th.BasicUser.Id, th.BasicTeam.Id, th.BasicPost.Id)
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), thread.UnreadMentions)
+	assert.Equal(t, int64(0), thread.UnreadReplies)
+
+	th.App.WaitForGoroutines()
+	lock.Lock()
+	assert.Equal(t, []string{dm.Id}, cleared, "only the channels with mentions should have their push notifications cleared")
+	lock.Unlock()
+
+	channelIds, err = th.App.MarkChannelsAsViewed(th.BasicUser.Id, "", true)
+	require.Nil(t, err)
+	assert.Empty(t, channelIds)
+}
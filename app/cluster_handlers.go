@@ -21,6 +21,13 @@ func (a *App) RegisterAllClusterMessageHandlers() {
 	a.Cluster.RegisterClusterMessageHandler(model.CLUSTER_EVENT_INVALIDATE_CACHE_FOR_CHANNEL, a.ClusterInvalidateCacheForChannelHandler)
 	a.Cluster.RegisterClusterMessageHandler(model.CLUSTER_EVENT_INVALIDATE_CACHE_FOR_USER, a.ClusterInvalidateCacheForUserHandler)
 	a.Cluster.RegisterClusterMessageHandler(model.CLUSTER_EVENT_CLEAR_SESSION_CACHE_FOR_USER, a.ClusterClearSessionCacheForUserHandler)
+	a.Cluster.RegisterClusterMessageHandler(model.CLUSTER_EVENT_CLEAR_SESSION_CACHE_FOR_ALL_USERS, a.ClusterClearSessionCacheForAllUsersHandler)
+	a.Cluster.RegisterClusterMessageHandler(model.CLUSTER_EVENT_UPDATE_MAINTENANCE_MODE, a.ClusterUpdateMaintenanceModeHandler)
+	a.Cluster.RegisterClusterMessageHandler(model.CLUSTER_EVENT_INVALIDATE_CACHE_FOR_LINK_BLOCKLIST, a.ClusterInvalidateCacheForLinkBlocklistHandler)
+	a.Cluster.RegisterClusterMessageHandler(model.CLUSTER_EVENT_UPDATE_FEATURE_FLAGS, a.ClusterUpdateFeatureFlagsHandler)
+	a.Cluster.RegisterClusterMessageHandler(model.CLUSTER_EVENT_UPDATE_NOTIFICATION_TRACE, a.ClusterUpdateNotificationTraceHandler)
+	a.Cluster.RegisterClusterMessageHandler(model.CLUSTER_EVENT_CLEAR_SESSION_CACHE_FOR_SESSIONS, a.ClusterClearSessionCacheForSessionsHandler)
+	a.Cluster.RegisterClusterMessageHandler(model.CLUSTER_EVENT_INVALIDATE_CACHE_FOR_OPEN_GRAPH, a.ClusterInvalidateCacheForOpenGraphHandler)
 }
 
 func (a *App) ClusterPublishHandler(msg *model.ClusterMessage) {
@@ -68,3 +75,38 @@ func (a *App) ClusterInvalidateCacheForUserHandler(msg *model.ClusterMessage) {
 func (a *App) ClusterClearSessionCacheForUserHandler(msg *model.ClusterMessage) {
 	a.ClearSessionCacheForUserSkipClusterSend(msg.Data)
 }
+
+func (a *App) ClusterClearSessionCacheForAllUsersHandler(msg *model.ClusterMessage) {
+	a.ClearSessionCacheForAllUsersSkipClusterSend()
+}
+
+func (a *App) ClusterUpdateMaintenanceModeHandler(msg *model.ClusterMessage) {
+	var mode *model.MaintenanceMode
+	if msg.Data != "" {
+		mode = model.MaintenanceModeFromJson(strings.NewReader(msg.Data))
+	}
+
+	a.updateMaintenanceMode(mode, false)
+}
+
+func (a *App) ClusterInvalidateCacheForLinkBlocklistHandler(msg *model.ClusterMessage) {
+	a.InvalidateLinkBlocklistSkipClusterSend()
+}
+
+func (a *App) ClusterUpdateFeatureFlagsHandler(msg *model.ClusterMessage) {
+	a.updateFeatureFlagOverrides(model.MapFromJson(strings.NewReader(msg.Data)), false)
+}
+
+func (a *App) ClusterUpdateNotificationTraceHandler(msg *model.ClusterMessage) {
+	if status := model.NotificationTraceStatusFromJson(strings.NewReader(msg.Data)); status != nil {
+		a.updateNotificationTrace(status, false)
+	}
+}
+
+func (a *App) ClusterClearSessionCacheForSessionsHandler(msg *model.ClusterMessage) {
+	a.clearSessionCacheForSessions(model.ArrayFromJson(strings.NewReader(msg.Data)), false)
+}
+
+func (a *App) ClusterInvalidateCacheForOpenGraphHandler(msg *model.ClusterMessage) {
+	a.InvalidateOpenGraphCacheForMessageSkipClusterSend(msg.Data)
+}
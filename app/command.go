@@ -156,6 +156,16 @@ func (a *App) ExecuteCommand(args *model.CommandArgs) (*model.CommandResponse, *
 	trigger := parts[0][1:]
 	trigger = strings.ToLower(trigger)
 	message := strings.Join(parts[1:], " ")
+
+	channel, err := a.GetChannel(args.ChannelId)
+	if err != nil {
+		return nil, err
+	}
+
+	if channel.IsCommandTriggerDisabled(trigger) {
+		return nil, model.NewAppError("ExecuteCommand", "api.command.execute_command.disabled_in_channel.app_error", map[string]interface{}{"Trigger": trigger}, "channel_id="+channel.Id, http.StatusForbidden)
+	}
+
 	provider := GetCommandProvider(trigger)
 
 	if provider != nil {
@@ -175,7 +185,6 @@ func (a *App) ExecuteCommand(args *model.CommandArgs) (*model.CommandResponse, *
 		return nil, model.NewAppError("ExecuteCommand", "api.command.disabled.app_error", nil, "", http.StatusNotImplemented)
 	}
 
-	chanChan := a.Srv.Store.Channel().Get(args.ChannelId, true)
 	teamChan := a.Srv.Store.Team().Get(args.TeamId)
 	userChan := a.Srv.Store.User().Get(args.UserId)
 
@@ -197,20 +206,19 @@ func (a *App) ExecuteCommand(args *model.CommandArgs) (*model.CommandResponse, *
 			user = ur.Data.(*model.User)
 		}
 
-		var channel *model.Channel
-		if cr := <-chanChan; cr.Err != nil {
-			return nil, cr.Err
-		} else {
-			channel = cr.Data.(*model.Channel)
-		}
-
 		teamCmds := result.Data.([]*model.Command)
 		for _, cmd := range teamCmds {
 			if trigger == cmd.Trigger {
+				if channel.DisableCustomCommands {
+					return nil, model.NewAppError("ExecuteCommand", "api.command.execute_command.custom_disabled_in_channel.app_error", map[string]interface{}{"Trigger": trigger}, "channel_id="+channel.Id, http.StatusForbidden)
+				}
+
 				mlog.Debug(fmt.Sprintf(utils.T("api.command.execute_command.debug"), trigger, args.UserId))
 
 				p := url.Values{}
-				p.Set("token", cmd.Token)
+				if !cmd.DisableLegacyToken {
+					p.Set("token", cmd.Token)
+				}
 
 				p.Set("team_id", cmd.TeamId)
 				p.Set("team_domain", team.Name)
@@ -230,16 +238,25 @@ func (a *App) ExecuteCommand(args *model.CommandArgs) (*model.CommandResponse, *
 					p.Set("response_url", args.SiteURL+"/hooks/commands/"+hook.Id)
 				}
 
+				// Requests that use GET are signed with their query strings instead of their bodies
+				body := p.Encode()
+
 				var req *http.Request
 				if cmd.Method == model.COMMAND_METHOD_GET {
 					req, _ = http.NewRequest(http.MethodGet, cmd.URL, nil)
-					req.URL.RawQuery = p.Encode()
+					req.URL.RawQuery = body
 				} else {
-					req, _ = http.NewRequest(http.MethodPost, cmd.URL, strings.NewReader(p.Encode()))
+					req, _ = http.NewRequest(http.MethodPost, cmd.URL, strings.NewReader(body))
 				}
 
 				req.Header.Set("Accept", "application/json")
-				req.Header.Set("Authorization", "Token "+cmd.Token)
+				if !cmd.DisableLegacyToken {
+					req.Header.Set("Authorization", "Token "+cmd.Token)
+				}
+				model.SetIntegrationSignatureHeaders(req.Header, cmd.SigningAlgorithm, cmd.SigningSecret, model.GetMillis(), []byte(body))
+				if args.RequestId != "" {
+					req.Header.Set(model.HEADER_REQUEST_ID, args.RequestId)
+				}
 				if cmd.Method == model.COMMAND_METHOD_POST {
 					req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 				}
@@ -368,6 +385,7 @@ func (a *App) UpdateCommand(oldCmd, updatedCmd *model.Command) (*model.Command,
 	updatedCmd.Trigger = strings.ToLower(updatedCmd.Trigger)
 	updatedCmd.Id = oldCmd.Id
 	updatedCmd.Token = oldCmd.Token
+	updatedCmd.SigningSecret = oldCmd.SigningSecret
 	updatedCmd.CreateAt = oldCmd.CreateAt
 	updatedCmd.UpdateAt = model.GetMillis()
 	updatedCmd.DeleteAt = oldCmd.DeleteAt
@@ -405,6 +423,21 @@ func (a *App) RegenCommandToken(cmd *model.Command) (*model.Command, *model.AppE
 	}
 }
 
+// RegenCommandSigningSecret replaces the secret that a slash command's requests are signed with.
+func (a *App) RegenCommandSigningSecret(cmd *model.Command) (*model.Command, *model.AppError) {
+	if !*a.Config().ServiceSettings.EnableCommands {
+		return nil, model.NewAppError("RegenCommandSigningSecret", "api.command.disabled.app_error", nil, "", http.StatusNotImplemented)
+	}
+
+	cmd.SigningSecret = model.NewRandomString(model.INTEGRATION_SIGNING_SECRET_LENGTH)
+
+	if result := <-a.Srv.Store.Command().Update(cmd); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return result.Data.(*model.Command), nil
+	}
+}
+
 func (a *App) DeleteCommand(commandId string) *model.AppError {
 	if !*a.Config().ServiceSettings.EnableCommands {
 		return model.NewAppError("DeleteCommand", "api.command.disabled.app_error", nil, "", http.StatusNotImplemented)
@@ -72,9 +72,7 @@ func (a *App) setCollapsePreference(args *model.CommandArgs, isCollapse bool) *m
 		return &model.CommandResponse{Text: args.T("api.command_expand_collapse.fail.app_error"), ResponseType: model.COMMAND_RESPONSE_TYPE_EPHEMERAL}
 	}
 
-	socketMessage := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_PREFERENCE_CHANGED, "", "", args.UserId, nil)
-	socketMessage.Add("preference", pref.ToJson())
-	a.Publish(socketMessage)
+	a.PublishPreferencesEvent(model.WEBSOCKET_EVENT_PREFERENCES_CHANGED, args.UserId, model.Preferences{pref})
 
 	var rmsg string
 
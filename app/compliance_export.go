@@ -0,0 +1,462 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const COMPLIANCE_EXPORT_BATCH_SIZE = 1000
+
+// complianceExporter writes the posts and channel memberships of a compliance export in one of the export
+// formats. Posts are written oldest first, followed by the memberships of each channel that had posts.
+type complianceExporter interface {
+	WritePost(post *model.ComplianceExportPost, files []*model.ComplianceExportFile) error
+	WriteChannelMembers(channel *model.ComplianceExportPost, members []*model.ChannelMemberHistoryResult) error
+	Close() error
+}
+
+// ExportCompliance writes the posts made from startTime through endTime, including deleted posts and the versions
+// of posts replaced by edits, along with who was in their channels at the time, to files in dir. The export is
+// recorded as a compliance report so that it can be audited. It doesn't need an enterprise license. Times in CSV
+// exports are given in location, while Actiance exports always use UTC.
+func (a *App) ExportCompliance(startTime int64, endTime int64, format string, dir string, location *time.Location) (*model.Compliance, *model.AppError) {
+	if format != model.COMPLIANCE_EXPORT_FORMAT_CSV && format != model.COMPLIANCE_EXPORT_FORMAT_ACTIANCE {
+		return nil, model.NewAppError("ExportCompliance", "app.compliance.export.format.app_error", map[string]interface{}{"Format": format}, "", http.StatusBadRequest)
+	}
+
+	job := &model.Compliance{
+		Desc:    fmt.Sprintf("Compliance export in %v format to %v", format, dir),
+		Type:    model.COMPLIANCE_TYPE_ADHOC,
+		Status:  model.COMPLIANCE_STATUS_RUNNING,
+		StartAt: startTime,
+		EndAt:   endTime,
+	}
+
+	if result := <-a.Srv.Store.Compliance().Save(job); result.Err != nil {
+		return nil, result.Err
+	} else {
+		job = result.Data.(*model.Compliance)
+	}
+
+	count, err := a.writeComplianceExport(job, format, dir, location)
+	if err != nil {
+		job.Status = model.COMPLIANCE_STATUS_FAILED
+	} else {
+		job.Status = model.COMPLIANCE_STATUS_FINISHED
+		job.Count = count
+	}
+
+	if result := <-a.Srv.Store.Compliance().Update(job); result.Err != nil && err == nil {
+		err = result.Err
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (a *App) writeComplianceExport(job *model.Compliance, format string, dir string, location *time.Location) (int, *model.AppError) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return 0, model.NewAppError("ExportCompliance", "app.compliance.export.write.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	var exporter complianceExporter
+	var err error
+	if format == model.COMPLIANCE_EXPORT_FORMAT_CSV {
+		exporter, err = newCsvComplianceExporter(dir, job.JobName(), location)
+	} else {
+		exporter, err = newActianceComplianceExporter(dir, job.JobName())
+	}
+	if err != nil {
+		return 0, model.NewAppError("ExportCompliance", "app.compliance.export.write.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	count, appErr := a.writeComplianceExportPosts(job, exporter)
+
+	if err := exporter.Close(); err != nil && appErr == nil {
+		appErr = model.NewAppError("ExportCompliance", "app.compliance.export.write.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	return count, appErr
+}
+
+func (a *App) writeComplianceExportPosts(job *model.Compliance, exporter complianceExporter) (int, *model.AppError) {
+	count := 0
+	channels := make(map[string]*model.ComplianceExportPost)
+
+	afterCreateAt, afterId := job.StartAt, ""
+	for {
+		result := <-a.Srv.Store.Compliance().ExportPosts(job.StartAt, job.EndAt, afterCreateAt, afterId, COMPLIANCE_EXPORT_BATCH_SIZE)
+		if result.Err != nil {
+			return count, result.Err
+		}
+
+		posts := result.Data.([]*model.ComplianceExportPost)
+		for _, post := range posts {
+			if err := exporter.WritePost(post, a.getComplianceExportFiles(post)); err != nil {
+				return count, model.NewAppError("ExportCompliance", "app.compliance.export.write.app_error", nil, err.Error(), http.StatusInternalServerError)
+			}
+
+			if _, ok := channels[post.ChannelId]; !ok {
+				channels[post.ChannelId] = post
+			}
+			count++
+		}
+
+		if len(posts) < COMPLIANCE_EXPORT_BATCH_SIZE {
+			break
+		}
+
+		last := posts[len(posts)-1]
+		afterCreateAt, afterId = last.PostCreateAt, last.PostId
+	}
+
+	channelIds := make([]string, 0, len(channels))
+	for channelId := range channels {
+		channelIds = append(channelIds, channelId)
+	}
+	sort.Strings(channelIds)
+
+	for _, channelId := range channelIds {
+		result := <-a.Srv.Store.ChannelMemberHistory().GetUsersInChannelDuring(job.StartAt, job.EndAt, channelId)
+		if result.Err != nil {
+			return count, result.Err
+		}
+
+		if err := exporter.WriteChannelMembers(channels[channelId], result.Data.([]*model.ChannelMemberHistoryResult)); err != nil {
+			return count, model.NewAppError("ExportCompliance", "app.compliance.export.write.app_error", nil, err.Error(), http.StatusInternalServerError)
+		}
+	}
+
+	return count, nil
+}
+
+// getComplianceExportFiles returns the files attached to a post. Files that have since been deleted are noted
+// as such instead of failing the export.
+func (a *App) getComplianceExportFiles(post *model.ComplianceExportPost) []*model.ComplianceExportFile {
+	files := make([]*model.ComplianceExportFile, 0, len(post.PostFileIds))
+	for _, fileId := range post.PostFileIds {
+		if result := <-a.Srv.Store.FileInfo().Get(fileId); result.Err != nil {
+			files = append(files, &model.ComplianceExportFile{Id: fileId, IsDeleted: true})
+		} else {
+			info := result.Data.(*model.FileInfo)
+			files = append(files, &model.ComplianceExportFile{Id: info.Id, Name: info.Name, Path: info.Path})
+		}
+	}
+
+	return files
+}
+
+type csvComplianceExporter struct {
+	postsFile   *os.File
+	posts       *csv.Writer
+	membersFile *os.File
+	members     *csv.Writer
+	location    *time.Location
+}
+
+func newCsvComplianceExporter(dir string, name string, location *time.Location) (*csvComplianceExporter, error) {
+	postsFile, err := os.Create(filepath.Join(dir, name+"-posts.csv"))
+	if err != nil {
+		return nil, err
+	}
+
+	membersFile, err := os.Create(filepath.Join(dir, name+"-channel-members.csv"))
+	if err != nil {
+		postsFile.Close()
+		return nil, err
+	}
+
+	e := &csvComplianceExporter{
+		postsFile:   postsFile,
+		posts:       csv.NewWriter(postsFile),
+		membersFile: membersFile,
+		members:     csv.NewWriter(membersFile),
+		location:    location,
+	}
+
+	if err := e.posts.Write(model.ComplianceExportPostHeader()); err != nil {
+		e.Close()
+		return nil, err
+	}
+
+	if err := e.members.Write(model.ComplianceExportChannelMemberHeader()); err != nil {
+		e.Close()
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (e *csvComplianceExporter) WritePost(post *model.ComplianceExportPost, files []*model.ComplianceExportFile) error {
+	return e.posts.Write(post.Row(files, e.location))
+}
+
+func (e *csvComplianceExporter) WriteChannelMembers(channel *model.ComplianceExportPost, members []*model.ChannelMemberHistoryResult) error {
+	for _, member := range members {
+		if err := e.members.Write(model.ComplianceExportChannelMemberRow(channel.ChannelName, member, e.location)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *csvComplianceExporter) Close() error {
+	e.posts.Flush()
+	e.members.Flush()
+
+	err := e.posts.Error()
+	if err == nil {
+		err = e.members.Error()
+	}
+
+	if closeErr := e.postsFile.Close(); err == nil {
+		err = closeErr
+	}
+
+	if closeErr := e.membersFile.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+type actianceExport struct {
+	XMLName       xml.Name                `xml:"FileDump"`
+	XMLNS         string                  `xml:"xmlns:xsi,attr"`
+	Conversations []*actianceConversation `xml:"Conversation"`
+}
+
+type actianceConversation struct {
+	Perspective string        `xml:"Perspective,attr"`
+	RoomId      string        `xml:"RoomID"`
+	StartTime   int64         `xml:"StartTimeUTC"`
+	Elements    []interface{} `xml:",any"`
+	EndTime     int64         `xml:"EndTimeUTC"`
+
+	sortTimes []int64
+}
+
+type actianceParticipant struct {
+	XMLName   xml.Name
+	LoginName string `xml:"LoginName"`
+	UserType  string `xml:"UserType"`
+	DateTime  int64  `xml:"DateTimeUTC"`
+	Email     string `xml:"CorporateEmailID"`
+}
+
+type actianceMessage struct {
+	XMLName        xml.Name `xml:"Message"`
+	MessageId      string   `xml:"MessageId"`
+	LoginName      string   `xml:"LoginName"`
+	UserType       string   `xml:"UserType"`
+	DateTime       int64    `xml:"DateTimeUTC"`
+	Content        string   `xml:"Content"`
+	RootId         string   `xml:"RootId,omitempty"`
+	OriginalId     string   `xml:"OriginalId,omitempty"`
+	Status         string   `xml:"Status,omitempty"`
+	DeleteDateTime int64    `xml:"DeletedDateTimeUTC,omitempty"`
+}
+
+type actianceFileTransfer struct {
+	XMLName   xml.Name `xml:"FileTransferStarted"`
+	LoginName string   `xml:"LoginName"`
+	UserType  string   `xml:"UserType"`
+	DateTime  int64    `xml:"DateTimeUTC"`
+	FileName  string   `xml:"UserFileName,omitempty"`
+	FilePath  string   `xml:"FileName"`
+	Status    string   `xml:"Status"`
+}
+
+// actianceComplianceExporter writes an export as an Actiance XML file with a conversation for each channel. The
+// conversations are only complete once the memberships of their channels are known, so they're kept in memory
+// until the export is closed.
+type actianceComplianceExporter struct {
+	path          string
+	conversations map[string]*actianceConversation
+}
+
+func newActianceComplianceExporter(dir string, name string) (*actianceComplianceExporter, error) {
+	path := filepath.Join(dir, name+".xml")
+
+	// make sure that the export can be written before reading any posts
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	file.Close()
+
+	return &actianceComplianceExporter{
+		path:          path,
+		conversations: make(map[string]*actianceConversation),
+	}, nil
+}
+
+func actianceLoginName(email string, username string) string {
+	if email != "" {
+		return email
+	}
+
+	return username
+}
+
+func (e *actianceComplianceExporter) conversation(post *model.ComplianceExportPost) *actianceConversation {
+	conversation, ok := e.conversations[post.ChannelId]
+	if !ok {
+		perspective := post.ChannelDisplayName
+		if post.ChannelType == model.CHANNEL_DIRECT {
+			perspective = "Direct Message"
+		} else if post.ChannelType == model.CHANNEL_GROUP {
+			perspective = "Group Message"
+		}
+
+		conversation = &actianceConversation{
+			Perspective: perspective,
+			RoomId:      post.ChannelType + " - " + post.ChannelId,
+			StartTime:   post.PostCreateAt / 1000,
+		}
+		e.conversations[post.ChannelId] = conversation
+	}
+
+	return conversation
+}
+
+func (c *actianceConversation) add(element interface{}, at int64) {
+	c.Elements = append(c.Elements, element)
+	c.sortTimes = append(c.sortTimes, at)
+
+	if at/1000 < c.StartTime {
+		c.StartTime = at / 1000
+	}
+	if at/1000 > c.EndTime {
+		c.EndTime = at / 1000
+	}
+}
+
+func (e *actianceComplianceExporter) WritePost(post *model.ComplianceExportPost, files []*model.ComplianceExportFile) error {
+	conversation := e.conversation(post)
+	loginName := actianceLoginName(post.UserEmail, post.UserUsername)
+
+	message := &actianceMessage{
+		MessageId:  post.PostId,
+		LoginName:  loginName,
+		UserType:   "user",
+		DateTime:   post.PostCreateAt / 1000,
+		Content:    post.PostMessage,
+		RootId:     post.PostRootId,
+		OriginalId: post.PostOriginalId,
+		Status:     post.Status(),
+	}
+	if post.PostDeleteAt > 0 {
+		message.DeleteDateTime = post.PostDeleteAt / 1000
+	}
+	conversation.add(message, post.PostCreateAt)
+
+	for _, file := range files {
+		transfer := &actianceFileTransfer{
+			LoginName: loginName,
+			UserType:  "user",
+			DateTime:  post.PostCreateAt / 1000,
+			FileName:  file.Name,
+			FilePath:  file.Path,
+			Status:    "Completed",
+		}
+		if file.IsDeleted {
+			transfer.FilePath = file.Id
+			transfer.Status = "Deleted"
+		}
+		conversation.add(transfer, post.PostCreateAt)
+	}
+
+	return nil
+}
+
+func (e *actianceComplianceExporter) WriteChannelMembers(channel *model.ComplianceExportPost, members []*model.ChannelMemberHistoryResult) error {
+	conversation := e.conversation(channel)
+
+	for _, member := range members {
+		loginName := actianceLoginName(member.UserEmail, member.Username)
+
+		conversation.add(&actianceParticipant{
+			XMLName:   xml.Name{Local: "ParticipantEntered"},
+			LoginName: loginName,
+			UserType:  "user",
+			DateTime:  member.JoinTime / 1000,
+			Email:     member.UserEmail,
+		}, member.JoinTime)
+
+		if member.LeaveTime != nil {
+			conversation.add(&actianceParticipant{
+				XMLName:   xml.Name{Local: "ParticipantLeft"},
+				LoginName: loginName,
+				UserType:  "user",
+				DateTime:  *member.LeaveTime / 1000,
+				Email:     member.UserEmail,
+			}, *member.LeaveTime)
+		}
+	}
+
+	return nil
+}
+
+func (e *actianceComplianceExporter) Close() error {
+	export := &actianceExport{XMLNS: "http://www.w3.org/2001/XMLSchema-instance"}
+
+	channelIds := make([]string, 0, len(e.conversations))
+	for channelId := range e.conversations {
+		channelIds = append(channelIds, channelId)
+	}
+	sort.Strings(channelIds)
+
+	for _, channelId := range channelIds {
+		conversation := e.conversations[channelId]
+		sort.Stable(conversation)
+		export.Conversations = append(export.Conversations, conversation)
+	}
+
+	file, err := os.Create(e.path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.WriteString(xml.Header); err != nil {
+		file.Close()
+		return err
+	}
+
+	encoder := xml.NewEncoder(file)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(export); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func (c *actianceConversation) Len() int {
+	return len(c.Elements)
+}
+
+func (c *actianceConversation) Less(i, j int) bool {
+	return c.sortTimes[i] < c.sortTimes[j]
+}
+
+func (c *actianceConversation) Swap(i, j int) {
+	c.Elements[i], c.Elements[j] = c.Elements[j], c.Elements[i]
+	c.sortTimes[i], c.sortTimes[j] = c.sortTimes[j], c.sortTimes[i]
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"encoding/csv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestExportCompliance(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	startTime := model.GetMillis()
+
+	info := &model.FileInfo{CreatorId: th.BasicUser.Id, Name: "kept.png", Path: "data/kept.png"}
+	result := <-th.App.Srv.Store.FileInfo().Save(info)
+	require.Nil(t, result.Err)
+	missing := &model.FileInfo{CreatorId: th.BasicUser.Id, Name: "missing.png", Path: "data/missing.png"}
+	result = <-th.App.Srv.Store.FileInfo().Save(missing)
+	require.Nil(t, result.Err)
+	missingFileId := missing.Id
+
+	attached, err := th.App.CreatePost(&model.Post{
+		UserId:    th.BasicUser.Id,
+		ChannelId: th.BasicChannel.Id,
+		Message:   "with attachments",
+		FileIds:   model.StringArray{info.Id, missingFileId},
+	}, th.BasicChannel, false)
+	require.Nil(t, err)
+
+	// The file is deleted after it's posted
+	result = <-th.App.Srv.Store.FileInfo().PermanentDelete(missingFileId)
+	require.Nil(t, result.Err)
+
+	edited := th.CreatePost(th.BasicChannel)
+	original := edited.Message
+	edited.Message = "edited message"
+	_, err = th.App.UpdatePost(edited, false)
+	require.Nil(t, err)
+
+	deleted := th.CreatePost(th.BasicChannel)
+	_, err = th.App.DeletePost(deleted.Id)
+	require.Nil(t, err)
+
+	endTime := model.GetMillis()
+
+	t.Run("csv", func(t *testing.T) {
+		dir, ioErr := ioutil.TempDir("", "compliance")
+		require.NoError(t, ioErr)
+		defer os.RemoveAll(dir)
+
+		job, err := th.App.ExportCompliance(startTime, endTime, model.COMPLIANCE_EXPORT_FORMAT_CSV, dir, time.UTC)
+		require.Nil(t, err)
+		assert.Equal(t, model.COMPLIANCE_STATUS_FINISHED, job.Status)
+		assert.Equal(t, 4, job.Count, "the edited post is exported along with its previous version")
+
+		saved := (<-th.App.Srv.Store.Compliance().Get(job.Id)).Data.(*model.Compliance)
+		assert.Equal(t, model.COMPLIANCE_STATUS_FINISHED, saved.Status)
+		assert.Equal(t, 4, saved.Count)
+
+		file, ioErr := os.Open(filepath.Join(dir, job.JobName()+"-posts.csv"))
+		require.NoError(t, ioErr)
+		defer file.Close()
+
+		rows, ioErr := csv.NewReader(file).ReadAll()
+		require.NoError(t, ioErr)
+		require.Len(t, rows, 5)
+		require.Equal(t, model.ComplianceExportPostHeader(), rows[0])
+
+		columns := make(map[string]int)
+		for i, name := range rows[0] {
+			columns[name] = i
+		}
+
+		byMessage := make(map[string][]string)
+		for _, row := range rows[1:] {
+			require.Len(t, row, len(rows[0]))
+			byMessage[row[columns["PostMessage"]]] = row
+		}
+
+		assert.Equal(t, "", byMessage["with attachments"][columns["PostStatus"]])
+		assert.Equal(t, "kept.png (data/kept.png); (deleted file "+missingFileId+")", byMessage["with attachments"][columns["PostFiles"]])
+		assert.Equal(t, attached.Id, byMessage["with attachments"][columns["PostId"]])
+
+		assert.Equal(t, model.COMPLIANCE_EXPORT_POST_STATUS_EDITED, byMessage["edited message"][columns["PostStatus"]])
+		assert.Equal(t, model.COMPLIANCE_EXPORT_POST_STATUS_PREVIOUS_VERSION, byMessage[original][columns["PostStatus"]])
+		assert.Equal(t, edited.Id, byMessage[original][columns["PostOriginalId"]])
+
+		assert.Equal(t, model.COMPLIANCE_EXPORT_POST_STATUS_DELETED, byMessage[deleted.Message][columns["PostStatus"]])
+		assert.NotEqual(t, "", byMessage[deleted.Message][columns["PostDeleteAt"]])
+
+		members, ioErr := ioutil.ReadFile(filepath.Join(dir, job.JobName()+"-channel-members.csv"))
+		require.NoError(t, ioErr)
+		assert.True(t, strings.HasPrefix(string(members), strings.Join(model.ComplianceExportChannelMemberHeader(), ",")+"\n"))
+	})
+
+	t.Run("csv in a timezone", func(t *testing.T) {
+		dir, ioErr := ioutil.TempDir("", "compliance")
+		require.NoError(t, ioErr)
+		defer os.RemoveAll(dir)
+
+		tokyo, ioErr := time.LoadLocation("Asia/Tokyo")
+		require.NoError(t, ioErr)
+
+		job, err := th.App.ExportCompliance(startTime, endTime, model.COMPLIANCE_EXPORT_FORMAT_CSV, dir, tokyo)
+		require.Nil(t, err)
+
+		file, ioErr := os.Open(filepath.Join(dir, job.JobName()+"-posts.csv"))
+		require.NoError(t, ioErr)
+		defer file.Close()
+
+		rows, ioErr := csv.NewReader(file).ReadAll()
+		require.NoError(t, ioErr)
+		require.Len(t, rows, 5)
+
+		for i, name := range rows[0] {
+			if name == "PostCreateAt" {
+				assert.Equal(t, time.Unix(0, attached.CreateAt*int64(time.Millisecond)).In(tokyo).Format(time.RFC3339), rows[1][i])
+				assert.True(t, strings.HasSuffix(rows[1][i], "+09:00"))
+			}
+		}
+	})
+
+	t.Run("actiance", func(t *testing.T) {
+		dir, ioErr := ioutil.TempDir("", "compliance")
+		require.NoError(t, ioErr)
+		defer os.RemoveAll(dir)
+
+		job, err := th.App.ExportCompliance(startTime, endTime, model.COMPLIANCE_EXPORT_FORMAT_ACTIANCE, dir, time.UTC)
+		require.Nil(t, err)
+
+		data, ioErr := ioutil.ReadFile(filepath.Join(dir, job.JobName()+".xml"))
+		require.NoError(t, ioErr)
+
+		xml := string(data)
+		assert.Contains(t, xml, "<FileDump")
+		assert.Contains(t, xml, "<RoomID>"+model.CHANNEL_OPEN+" - "+th.BasicChannel.Id+"</RoomID>")
+		assert.Contains(t, xml, "<Status>"+model.COMPLIANCE_EXPORT_POST_STATUS_PREVIOUS_VERSION+"</Status>")
+		assert.Contains(t, xml, "<Status>"+model.COMPLIANCE_EXPORT_POST_STATUS_DELETED+"</Status>")
+		assert.Contains(t, xml, "<FileName>"+missingFileId+"</FileName>")
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		_, err := th.App.ExportCompliance(startTime, endTime, "pdf", os.TempDir(), time.UTC)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.compliance.export.format.app_error", err.Id)
+	})
+}
@@ -16,6 +16,7 @@ import (
 	"runtime/debug"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/mattermost/mattermost-server/mlog"
 	"github.com/mattermost/mattermost-server/model"
@@ -40,13 +41,14 @@ func (a *App) UpdateConfig(f func(*model.Config)) {
 	old := a.Config()
 	updated := old.Clone()
 	f(updated)
+	a.applyFeatureFlagOverrides(old, updated)
 	a.config.Store(updated)
 
 	a.InvokeConfigListeners(old, updated)
 }
 
 func (a *App) PersistConfig() {
-	utils.SaveConfig(a.ConfigFileName(), a.Config())
+	utils.SaveConfig(a.ConfigFileName(), a.configWithoutFeatureFlagOverrides())
 }
 
 func (a *App) LoadConfig(configFile string) *model.AppError {
@@ -57,15 +59,25 @@ func (a *App) LoadConfig(configFile string) *model.AppError {
 		return err
 	}
 
+	if err := a.ValidateDisabledAPIEndpoints(cfg); err != nil {
+		return err
+	}
+
 	a.configFile = configPath
 
+	a.setLoadedConfig(old, cfg, envConfig)
+	return nil
+}
+
+// setLoadedConfig makes a config that's been loaded from the config file the active one.
+func (a *App) setLoadedConfig(old, cfg *model.Config, envConfig map[string]interface{}) {
+	a.applyFeatureFlagOverrides(nil, cfg)
 	a.config.Store(cfg)
 	a.envConfig = envConfig
 
 	a.siteURL = strings.TrimRight(*cfg.ServiceSettings.SiteURL, "/")
 
 	a.InvokeConfigListeners(old, cfg)
-	return nil
 }
 
 func (a *App) ReloadConfig() *model.AppError {
@@ -93,8 +105,13 @@ func (a *App) ClientConfigHash() string {
 
 func (a *App) EnableConfigWatch() {
 	if a.configWatcher == nil && !a.disableConfigWatch {
-		configWatcher, err := utils.NewConfigWatcher(a.ConfigFileName(), func() {
-			a.ReloadConfig()
+		debounce := time.Duration(*a.Config().ServiceSettings.ConfigWatchDebounceMilliseconds) * time.Millisecond
+		configWatcher, err := utils.NewConfigWatcher(a.ConfigFileName(), debounce, func(cfg *model.Config, envConfig map[string]interface{}) {
+			debug.FreeOSMemory()
+			a.setLoadedConfig(a.Config(), cfg, envConfig)
+
+			// start/restart email batching job if necessary
+			a.InitEmailBatching()
 		})
 		if err != nil {
 			mlog.Error(fmt.Sprint(err))
@@ -215,6 +232,7 @@ func (a *App) regenerateClientConfig() {
 		der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
 		a.clientConfig["AsymmetricSigningPublicKey"] = base64.StdEncoding.EncodeToString(der)
 	}
+	a.applyAnnouncementToClientConfig(a.clientConfig)
 	clientConfigJSON, _ := json.Marshal(a.clientConfig)
 	a.clientConfigHash = fmt.Sprintf("%x", md5.Sum(clientConfigJSON))
 }
@@ -229,6 +247,9 @@ func (a *App) Desanitize(cfg *model.Config) {
 	if *cfg.FileSettings.PublicLinkSalt == model.FAKE_SETTING {
 		*cfg.FileSettings.PublicLinkSalt = *actual.FileSettings.PublicLinkSalt
 	}
+	if *cfg.ServiceSettings.PostActionSigningSecret == model.FAKE_SETTING {
+		*cfg.ServiceSettings.PostActionSigningSecret = *actual.ServiceSettings.PostActionSigningSecret
+	}
 	if cfg.FileSettings.AmazonS3SecretAccessKey == model.FAKE_SETTING {
 		cfg.FileSettings.AmazonS3SecretAccessKey = actual.FileSettings.AmazonS3SecretAccessKey
 	}
@@ -239,6 +260,9 @@ func (a *App) Desanitize(cfg *model.Config) {
 	if cfg.EmailSettings.SMTPPassword == model.FAKE_SETTING {
 		cfg.EmailSettings.SMTPPassword = actual.EmailSettings.SMTPPassword
 	}
+	if *cfg.EmailSettings.BounceWebhookSecret == model.FAKE_SETTING {
+		*cfg.EmailSettings.BounceWebhookSecret = *actual.EmailSettings.BounceWebhookSecret
+	}
 
 	if cfg.GitLabSettings.Secret == model.FAKE_SETTING {
 		cfg.GitLabSettings.Secret = actual.GitLabSettings.Secret
@@ -247,6 +271,9 @@ func (a *App) Desanitize(cfg *model.Config) {
 	if *cfg.SqlSettings.DataSource == model.FAKE_SETTING {
 		*cfg.SqlSettings.DataSource = *actual.SqlSettings.DataSource
 	}
+	if *cfg.SqlSettings.DataSourceFailover == model.FAKE_SETTING {
+		*cfg.SqlSettings.DataSourceFailover = *actual.SqlSettings.DataSourceFailover
+	}
 	if cfg.SqlSettings.AtRestEncryptKey == model.FAKE_SETTING {
 		cfg.SqlSettings.AtRestEncryptKey = actual.SqlSettings.AtRestEncryptKey
 	}
@@ -255,12 +282,21 @@ func (a *App) Desanitize(cfg *model.Config) {
 		*cfg.ElasticsearchSettings.Password = *actual.ElasticsearchSettings.Password
 	}
 
+	if *cfg.CaptchaSettings.SecretKey == model.FAKE_SETTING {
+		*cfg.CaptchaSettings.SecretKey = *actual.CaptchaSettings.SecretKey
+	}
+
+	// A restored version of the config can have different replicas than the current one
 	for i := range cfg.SqlSettings.DataSourceReplicas {
-		cfg.SqlSettings.DataSourceReplicas[i] = actual.SqlSettings.DataSourceReplicas[i]
+		if cfg.SqlSettings.DataSourceReplicas[i] == model.FAKE_SETTING && i < len(actual.SqlSettings.DataSourceReplicas) {
+			cfg.SqlSettings.DataSourceReplicas[i] = actual.SqlSettings.DataSourceReplicas[i]
+		}
 	}
 
 	for i := range cfg.SqlSettings.DataSourceSearchReplicas {
-		cfg.SqlSettings.DataSourceSearchReplicas[i] = actual.SqlSettings.DataSourceSearchReplicas[i]
+		if cfg.SqlSettings.DataSourceSearchReplicas[i] == model.FAKE_SETTING && i < len(actual.SqlSettings.DataSourceSearchReplicas) {
+			cfg.SqlSettings.DataSourceSearchReplicas[i] = actual.SqlSettings.DataSourceSearchReplicas[i]
+		}
 	}
 }
 
@@ -288,6 +324,7 @@ func (a *App) ClientConfigWithComputed() map[string]string {
 	// by the client.
 	respCfg["NoAccounts"] = strconv.FormatBool(a.IsFirstUserAccount())
 	respCfg["MaxPostSize"] = strconv.Itoa(a.MaxPostSize())
+	respCfg["DatabaseReadOnly"] = strconv.FormatBool(a.Srv.Store.IsReadOnly())
 
 	return respCfg
 }
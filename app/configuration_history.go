@@ -0,0 +1,184 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+const CONFIGURATION_HISTORY_REENCRYPT_BATCH_SIZE = 100
+
+// RecordConfigHistory saves cfg as a new version of the config along with the settings that changed from oldCfg,
+// and then deletes the oldest versions past the limit that cfg keeps. The secrets in the version are encrypted with
+// cfg's at rest encryption key.
+func (a *App) RecordConfigHistory(oldCfg *model.Config, cfg *model.Config, userId string, source string) (*model.ConfigurationHistory, *model.AppError) {
+	sanitized, secrets := model.SplitConfigSecrets(cfg)
+
+	b, _ := json.Marshal(secrets)
+	encrypted, err := utils.EncryptAtRest(cfg.SqlSettings.AtRestEncryptKey, b)
+	if err != nil {
+		return nil, model.NewAppError("RecordConfigHistory", "app.configuration_history.encrypt.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	result := <-a.Srv.Store.ConfigurationHistory().Save(&model.ConfigurationHistory{
+		UserId:  userId,
+		Source:  source,
+		Summary: model.ConfigChangesSummary(model.DiffConfigs(oldCfg, cfg)),
+		Config:  sanitized.ToJson(),
+		Secrets: encrypted,
+	})
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	history := result.Data.(*model.ConfigurationHistory)
+
+	if result := <-a.Srv.Store.ConfigurationHistory().PermanentDeleteAllButLatest(*cfg.ServiceSettings.ConfigHistoryMaxVersions); result.Err != nil {
+		mlog.Warn(fmt.Sprintf("Failed to delete old versions of the config: %v", result.Err.Error()))
+	}
+
+	return history, nil
+}
+
+// GetConfigHistoryPage returns the saved versions of the config from newest to oldest.
+func (a *App) GetConfigHistoryPage(page int, perPage int) ([]*model.ConfigurationHistory, *model.AppError) {
+	result := <-a.Srv.Store.ConfigurationHistory().GetPage(page*perPage, perPage)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.([]*model.ConfigurationHistory), nil
+}
+
+func (a *App) GetConfigHistory(historyId string) (*model.ConfigurationHistory, *model.AppError) {
+	result := <-a.Srv.Store.ConfigurationHistory().Get(historyId)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.ConfigurationHistory), nil
+}
+
+// GetConfigHistoryDiff returns the settings that changed between the version of the config saved before the given
+// one and the given one. The values of secrets are replaced with FAKE_SETTING.
+func (a *App) GetConfigHistoryDiff(historyId string) ([]*model.ConfigChange, *model.AppError) {
+	history, err := a.GetConfigHistory(historyId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := <-a.Srv.Store.ConfigurationHistory().GetPrevious(history)
+	if result.Err != nil {
+		if result.Err.StatusCode == http.StatusNotFound {
+			return nil, model.NewAppError("GetConfigHistoryDiff", "app.configuration_history.diff.no_previous.app_error", nil, "id="+historyId, http.StatusNotFound)
+		}
+		return nil, result.Err
+	}
+	previous := result.Data.(*model.ConfigurationHistory)
+
+	before, err := a.loadConfigHistory(previous)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := a.loadConfigHistory(history)
+	if err != nil {
+		return nil, err
+	}
+
+	return model.DiffConfigs(before, after), nil
+}
+
+// RestoreConfigHistory saves the given version of the config, as long as it's still valid, as a new version made by
+// the user. The at rest encryption key isn't stored with the versions, so the current one is kept.
+func (a *App) RestoreConfigHistory(historyId string, userId string) *model.AppError {
+	history, err := a.GetConfigHistory(historyId)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := a.loadConfigHistory(history)
+	if err != nil {
+		return err
+	}
+
+	// Plugin uploads can't be toggled through the API, so they can't be by restoring a version from before they were
+	cfg.PluginSettings.EnableUploads = a.Config().PluginSettings.EnableUploads
+
+	if err := a.SaveConfigAs(cfg, true, userId, model.CONFIGURATION_HISTORY_SOURCE_RESTORE); err != nil {
+		return err
+	}
+
+	mlog.Info("Restored a previous version of the config", mlog.String("configuration_history_id", historyId), mlog.String("user_id", userId))
+
+	return nil
+}
+
+// loadConfigHistory returns the config that was saved as the given version, with its secrets decrypted using the
+// current at rest encryption key.
+func (a *App) loadConfigHistory(history *model.ConfigurationHistory) (*model.Config, *model.AppError) {
+	sanitized := model.ConfigFromJson(strings.NewReader(history.Config))
+	if sanitized == nil {
+		return nil, model.NewAppError("loadConfigHistory", "app.configuration_history.load.app_error", nil, "id="+history.Id, http.StatusInternalServerError)
+	}
+
+	b, err := utils.DecryptAtRest(a.Config().SqlSettings.AtRestEncryptKey, history.Secrets)
+	if err != nil {
+		return nil, model.NewAppError("loadConfigHistory", "app.configuration_history.decrypt.app_error", nil, "id="+history.Id+", "+err.Error(), http.StatusInternalServerError)
+	}
+
+	var secrets map[string]interface{}
+	if err := json.Unmarshal(b, &secrets); err != nil {
+		return nil, model.NewAppError("loadConfigHistory", "app.configuration_history.load.app_error", nil, "id="+history.Id+", "+err.Error(), http.StatusInternalServerError)
+	}
+
+	return model.JoinConfigSecrets(sanitized, secrets)
+}
+
+// reencryptConfigHistory encrypts the secrets of every saved version of the config with newKey instead of oldKey,
+// updating them all at once so that either every version is or none are. It fails without changing anything if any
+// version can't be decrypted with oldKey. The secrets that the versions had before are returned so that they can be
+// put back.
+func (a *App) reencryptConfigHistory(oldKey string, newKey string) (map[string]string, *model.AppError) {
+	previous := make(map[string]string)
+	reencrypted := make(map[string]string)
+	for offset := 0; ; offset += CONFIGURATION_HISTORY_REENCRYPT_BATCH_SIZE {
+		result := <-a.Srv.Store.ConfigurationHistory().GetPage(offset, CONFIGURATION_HISTORY_REENCRYPT_BATCH_SIZE)
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		list := result.Data.([]*model.ConfigurationHistory)
+
+		for _, history := range list {
+			secrets, err := utils.DecryptAtRest(oldKey, history.Secrets)
+			if err != nil {
+				return nil, model.NewAppError("reencryptConfigHistory", "app.configuration_history.decrypt.app_error", nil, "id="+history.Id+", "+err.Error(), http.StatusInternalServerError)
+			}
+
+			encrypted, err := utils.EncryptAtRest(newKey, secrets)
+			if err != nil {
+				return nil, model.NewAppError("reencryptConfigHistory", "app.configuration_history.encrypt.app_error", nil, "id="+history.Id+", "+err.Error(), http.StatusInternalServerError)
+			}
+
+			previous[history.Id] = history.Secrets
+			reencrypted[history.Id] = encrypted
+		}
+
+		if len(list) < CONFIGURATION_HISTORY_REENCRYPT_BATCH_SIZE {
+			break
+		}
+	}
+
+	if result := <-a.Srv.Store.ConfigurationHistory().UpdateSecrets(reencrypted); result.Err != nil {
+		return nil, result.Err
+	}
+
+	return previous, nil
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestRecordConfigHistory(t *testing.T) {
+	th := Setup()
+	defer th.TearDown()
+
+	t.Run("retention", func(t *testing.T) {
+		cfg := th.App.Config().Clone()
+		*cfg.ServiceSettings.ConfigHistoryMaxVersions = 2
+
+		var saved []*model.ConfigurationHistory
+		for i := 0; i < 3; i++ {
+			history, err := th.App.RecordConfigHistory(th.App.Config(), cfg, "", model.CONFIGURATION_HISTORY_SOURCE_SERVER)
+			require.Nil(t, err)
+			saved = append(saved, history)
+		}
+
+		list, err := th.App.GetConfigHistoryPage(0, 10)
+		require.Nil(t, err)
+		assert.Len(t, list, 2, "only the newest versions should be kept")
+
+		_, err = th.App.GetConfigHistory(saved[0].Id)
+		assert.NotNil(t, err)
+		_, err = th.App.GetConfigHistory(saved[2].Id)
+		assert.Nil(t, err)
+	})
+
+	t.Run("rotated at rest encryption key", func(t *testing.T) {
+		makeConfigHistoryReadableForTest(t, th)
+
+		cfg := th.App.Config().Clone()
+		cfg.EmailSettings.SMTPPassword = "password"
+		history, err := th.App.RecordConfigHistory(th.App.Config(), cfg, "", model.CONFIGURATION_HISTORY_SOURCE_SERVER)
+		require.Nil(t, err)
+
+		result, err := th.App.RotateSecrets(&SecretRotation{OldAtRestEncryptKey: th.App.Config().SqlSettings.AtRestEncryptKey})
+		require.Nil(t, err)
+		assert.True(t, result.ConfigVersionsReencrypted >= 1)
+
+		_, err = th.App.loadConfigHistory(history)
+		require.NotNil(t, err, "the version as it was before the rotation should be encrypted with the old key")
+
+		history, err = th.App.GetConfigHistory(history.Id)
+		require.Nil(t, err)
+		loaded, err := th.App.loadConfigHistory(history)
+		require.Nil(t, err)
+		assert.Equal(t, "password", loaded.EmailSettings.SMTPPassword)
+	})
+}
@@ -0,0 +1,179 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+var allowedMethods []string = []string{
+	"POST",
+	"GET",
+	"OPTIONS",
+	"PUT",
+	"PATCH",
+	"DELETE",
+}
+
+// corsExcludedPathPrefixes are the admin route groups that never allow cross-origin requests, regardless of the
+// CORS settings, so that a page on another site can't read or change how the server is set up. They're matched a
+// path segment at a time, and a "*" segment matches any single segment such as an id, so that new admin routes under
+// these groups are excluded without having to be listed.
+var corsExcludedPathPrefixes = []string{
+	"/analytics",
+	"/audits",
+	"/brand",
+	"/caches",
+	"/channels/*/aliases",
+	"/channels/*/members/history",
+	"/channels/*/moderations",
+	"/channels/*/scheme",
+	"/cluster",
+	"/compliance",
+	"/config",
+	"/data_retention",
+	"/database",
+	"/elasticsearch",
+	"/email",
+	"/emoji/stats",
+	"/emoji/unused",
+	"/file",
+	"/files/usage",
+	"/jobs",
+	"/ldap",
+	"/license",
+	"/logs",
+	"/oauth/apps",
+	"/plugins",
+	"/provisioning_tokens",
+	"/roles",
+	"/saml",
+	"/schemes",
+	"/system",
+	"/team_templates",
+	"/teams/*/scheme",
+	"/teams/from_template",
+	"/terms_of_service",
+	"/users/*/notification_trace",
+	"/users/*/roles",
+	"/users/*/storage_exempt",
+	"/users/attributes/fields",
+	"/users/tokens",
+	"/users/username_policy",
+}
+
+// corsAllowedPaths are the public reads under the excluded route groups that clients on other origins still need.
+var corsAllowedPaths = map[string]bool{
+	model.API_URL_SUFFIX + "/config/client":  true,
+	model.API_URL_SUFFIX + "/license/client": true,
+}
+
+// CorsWrapper adds the CORS headers allowed by ServiceSettings to responses for requests from other origins. It
+// answers preflight requests itself without passing them on to the router.
+type CorsWrapper struct {
+	config model.ConfigFunc
+	router *mux.Router
+}
+
+func (cw *CorsWrapper) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if origin := r.Header.Get("Origin"); origin != "" && !isCorsExcludedPath(r.URL.Path) {
+		cw.writeCorsHeaders(w, r, origin)
+	}
+
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	cw.router.ServeHTTP(w, r)
+}
+
+func (cw *CorsWrapper) writeCorsHeaders(w http.ResponseWriter, r *http.Request, origin string) {
+	settings := &cw.config().ServiceSettings
+
+	allowed, explicitlyAllowed := matchCorsOrigin(origin, *settings.AllowCorsFrom)
+	if !allowed {
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	w.Header().Add("Vary", "Origin")
+
+	// credentials are only allowed for origins that are listed so that they can't be sent from any site
+	if *settings.CorsAllowCredentials && explicitlyAllowed {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	if r.Method != "OPTIONS" {
+		if exposed := strings.Fields(*settings.CorsExposedHeaders); len(exposed) > 0 {
+			w.Header().Set("Access-Control-Expose-Headers", strings.Join(exposed, ", "))
+		}
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(allowedMethods, ", "))
+
+	if allowedHeaders := strings.Fields(*settings.CorsAllowedHeaders); len(allowedHeaders) > 0 {
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(allowedHeaders, ", "))
+	} else {
+		w.Header().Set("Access-Control-Allow-Headers", r.Header.Get("Access-Control-Request-Headers"))
+	}
+
+	if *settings.CorsMaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(*settings.CorsMaxAge))
+	}
+}
+
+// matchCorsOrigin returns whether the origin is allowed and whether it was allowed by being listed rather than
+// by every origin being allowed.
+func matchCorsOrigin(origin, allowedOrigins string) (allowed bool, explicitlyAllowed bool) {
+	for _, allowedOrigin := range strings.Fields(allowedOrigins) {
+		if allowedOrigin == "*" {
+			allowed = true
+		} else if utils.MatchOrigin(origin, allowedOrigin) {
+			return true, true
+		}
+	}
+
+	return allowed, false
+}
+
+func isCorsExcludedPath(urlPath string) bool {
+	urlPath = strings.TrimSuffix(path.Clean(urlPath), "/")
+	if corsAllowedPaths[urlPath] || !strings.HasPrefix(urlPath, model.API_URL_SUFFIX+"/") {
+		return false
+	}
+
+	segments := strings.Split(strings.TrimPrefix(urlPath, model.API_URL_SUFFIX+"/"), "/")
+	for _, prefix := range corsExcludedPathPrefixes {
+		if matchPathSegments(segments, strings.Split(strings.TrimPrefix(prefix, "/"), "/")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchPathSegments returns whether the path starts with the given prefix segments, where a "*" prefix segment
+// matches any single path segment.
+func matchPathSegments(segments, prefix []string) bool {
+	if len(segments) < len(prefix) {
+		return false
+	}
+
+	for i, segment := range prefix {
+		if segment != "*" && segment != segments[i] {
+			return false
+		}
+	}
+
+	return true
+}
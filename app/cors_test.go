@@ -0,0 +1,150 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func newCorsWrapperForTest(updateConfig func(*model.Config)) (*CorsWrapper, *int) {
+	cfg := &model.Config{}
+	cfg.SetDefaults()
+	updateConfig(cfg)
+
+	handled := 0
+	router := mux.NewRouter()
+	router.PathPrefix("/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handled++
+	})
+
+	return &CorsWrapper{config: func() *model.Config { return cfg }, router: router}, &handled
+}
+
+func serveCorsRequestForTest(cw *CorsWrapper, method, path, origin string) *http.Response {
+	r := httptest.NewRequest(method, "http://localhost:8065"+path, nil)
+	if origin != "" {
+		r.Header.Set("Origin", origin)
+	}
+	if method == "OPTIONS" {
+		r.Header.Set("Access-Control-Request-Method", "POST")
+		r.Header.Set("Access-Control-Request-Headers", "X-Requested-With")
+	}
+
+	w := httptest.NewRecorder()
+	cw.ServeHTTP(w, r)
+	return w.Result()
+}
+
+func TestCorsWrapper(t *testing.T) {
+	t.Run("disabled by default", func(t *testing.T) {
+		cw, handled := newCorsWrapperForTest(func(cfg *model.Config) {})
+
+		resp := serveCorsRequestForTest(cw, "GET", "/api/v4/users/me", "https://example.com")
+		assert.Equal(t, "", resp.Header.Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, 1, *handled)
+	})
+
+	t.Run("wildcard subdomains", func(t *testing.T) {
+		cw, _ := newCorsWrapperForTest(func(cfg *model.Config) {
+			*cfg.ServiceSettings.AllowCorsFrom = "https://*.example.com"
+		})
+
+		resp := serveCorsRequestForTest(cw, "GET", "/api/v4/users/me", "https://chat.example.com")
+		assert.Equal(t, "https://chat.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "Origin", resp.Header.Get("Vary"))
+
+		resp = serveCorsRequestForTest(cw, "GET", "/api/v4/users/me", "https://example.com")
+		assert.Equal(t, "", resp.Header.Get("Access-Control-Allow-Origin"))
+
+		resp = serveCorsRequestForTest(cw, "GET", "/api/v4/users/me", "https://chat.example.com.evil.com")
+		assert.Equal(t, "", resp.Header.Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("preflight requests", func(t *testing.T) {
+		cw, handled := newCorsWrapperForTest(func(cfg *model.Config) {
+			*cfg.ServiceSettings.AllowCorsFrom = "https://example.com"
+			*cfg.ServiceSettings.CorsMaxAge = 600
+		})
+
+		resp := serveCorsRequestForTest(cw, "OPTIONS", "/api/v4/posts", "https://example.com")
+		assert.Equal(t, "https://example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "POST, GET, OPTIONS, PUT, PATCH, DELETE", resp.Header.Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, "X-Requested-With", resp.Header.Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "600", resp.Header.Get("Access-Control-Max-Age"))
+		assert.Equal(t, 0, *handled, "preflight requests shouldn't reach the router")
+	})
+
+	t.Run("configured headers", func(t *testing.T) {
+		cw, _ := newCorsWrapperForTest(func(cfg *model.Config) {
+			*cfg.ServiceSettings.AllowCorsFrom = "https://example.com"
+			*cfg.ServiceSettings.CorsAllowedHeaders = "Content-Type Authorization"
+			*cfg.ServiceSettings.CorsExposedHeaders = "Etag X-Request-Id"
+		})
+
+		resp := serveCorsRequestForTest(cw, "OPTIONS", "/api/v4/posts", "https://example.com")
+		assert.Equal(t, "Content-Type, Authorization", resp.Header.Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "", resp.Header.Get("Access-Control-Max-Age"))
+		assert.Equal(t, "", resp.Header.Get("Access-Control-Expose-Headers"))
+
+		resp = serveCorsRequestForTest(cw, "GET", "/api/v4/posts", "https://example.com")
+		assert.Equal(t, "Etag, X-Request-Id", resp.Header.Get("Access-Control-Expose-Headers"))
+		assert.Equal(t, "", resp.Header.Get("Access-Control-Allow-Methods"))
+	})
+
+	t.Run("credentialed requests", func(t *testing.T) {
+		cw, _ := newCorsWrapperForTest(func(cfg *model.Config) {
+			*cfg.ServiceSettings.AllowCorsFrom = "https://*.example.com"
+			*cfg.ServiceSettings.CorsAllowCredentials = true
+		})
+
+		resp := serveCorsRequestForTest(cw, "GET", "/api/v4/users/me", "https://chat.example.com")
+		assert.Equal(t, "true", resp.Header.Get("Access-Control-Allow-Credentials"))
+
+		resp = serveCorsRequestForTest(cw, "OPTIONS", "/api/v4/users/me", "https://chat.example.com")
+		assert.Equal(t, "true", resp.Header.Get("Access-Control-Allow-Credentials"))
+
+		resp = serveCorsRequestForTest(cw, "GET", "/api/v4/users/me", "https://other.com")
+		assert.Equal(t, "", resp.Header.Get("Access-Control-Allow-Credentials"))
+	})
+
+	t.Run("credentials aren't allowed for every origin", func(t *testing.T) {
+		cw, _ := newCorsWrapperForTest(func(cfg *model.Config) {
+			*cfg.ServiceSettings.AllowCorsFrom = "*"
+			*cfg.ServiceSettings.CorsAllowCredentials = true
+		})
+
+		resp := serveCorsRequestForTest(cw, "GET", "/api/v4/users/me", "https://other.com")
+		assert.Equal(t, "https://other.com", resp.Header.Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "", resp.Header.Get("Access-Control-Allow-Credentials"))
+	})
+
+	t.Run("admin routes are excluded", func(t *testing.T) {
+		cw, _ := newCorsWrapperForTest(func(cfg *model.Config) {
+			*cfg.ServiceSettings.AllowCorsFrom = "*"
+		})
+
+		for _, path := range []string{"/api/v4/config", "/api/v4/config/", "/api/v4//config", "/api/v4/config/reload", "/api/v4/license", "/api/v4/caches/invalidate",
+			"/api/v4/config/history/abc/restore", "/api/v4/system/maintenance", "/api/v4/plugins/com.example/activate",
+			"/api/v4/users/abc/roles", "/api/v4/channels/abc/moderations"} {
+			resp := serveCorsRequestForTest(cw, "GET", path, "https://example.com")
+			assert.Equal(t, "", resp.Header.Get("Access-Control-Allow-Origin"), path)
+
+			resp = serveCorsRequestForTest(cw, "OPTIONS", path, "https://example.com")
+			assert.Equal(t, "", resp.Header.Get("Access-Control-Allow-Origin"), path)
+			assert.Equal(t, "", resp.Header.Get("Access-Control-Allow-Methods"), path)
+		}
+
+		for _, path := range []string{"/api/v4/config/client", "/api/v4/license/client", "/api/v4/configs", "/api/v4/users/abc", "/api/v4/channels/abc/members"} {
+			resp := serveCorsRequestForTest(cw, "GET", path, "https://example.com")
+			assert.Equal(t, "https://example.com", resp.Header.Get("Access-Control-Allow-Origin"), path)
+		}
+	})
+}
@@ -0,0 +1,193 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+func (a *App) CreateCustomGroup(group *model.CustomGroup, creatorId string) (*model.CustomGroup, *model.AppError) {
+	group.Id = ""
+	group.CreatorId = creatorId
+
+	if err := a.checkCustomGroupNameAvailable(group.Name); err != nil {
+		return nil, err
+	}
+
+	result := <-a.Srv.Store.CustomGroup().Save(group)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.CustomGroup), nil
+}
+
+func (a *App) GetCustomGroup(groupId string) (*model.CustomGroup, *model.AppError) {
+	result := <-a.Srv.Store.CustomGroup().Get(groupId)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.CustomGroup), nil
+}
+
+func (a *App) GetCustomGroups(page int, perPage int) ([]*model.CustomGroup, *model.AppError) {
+	result := <-a.Srv.Store.CustomGroup().GetAll(page*perPage, perPage)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.([]*model.CustomGroup), nil
+}
+
+func (a *App) PatchCustomGroup(group *model.CustomGroup, patch *model.CustomGroupPatch) (*model.CustomGroup, *model.AppError) {
+	oldName := group.Name
+
+	group.Patch(patch)
+
+	if strings.ToLower(group.Name) != oldName {
+		if err := a.checkCustomGroupNameAvailable(group.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	result := <-a.Srv.Store.CustomGroup().Update(group)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.CustomGroup), nil
+}
+
+func (a *App) DeleteCustomGroup(groupId string) *model.AppError {
+	if result := <-a.Srv.Store.CustomGroup().Delete(groupId); result.Err != nil {
+		return result.Err
+	}
+
+	return nil
+}
+
+func (a *App) GetCustomGroupMembers(groupId string) ([]*model.GroupMember, *model.AppError) {
+	result := <-a.Srv.Store.CustomGroup().GetMembers(groupId)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.([]*model.GroupMember), nil
+}
+
+// AddCustomGroupMembers adds the given users to the group. Users who are already in the group are
+// left as they are.
+func (a *App) AddCustomGroupMembers(groupId string, userIds []string) ([]*model.GroupMember, *model.AppError) {
+	userIds = utils.RemoveDuplicatesFromStringArray(userIds)
+
+	result := <-a.Srv.Store.User().GetProfileByIds(userIds, true)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	if users := result.Data.([]*model.User); len(users) != len(userIds) {
+		return nil, model.NewAppError("AddCustomGroupMembers", "app.custom_group.add_members.invalid_user.app_error", nil, "group_id="+groupId, http.StatusBadRequest)
+	}
+
+	members := make([]*model.GroupMember, 0, len(userIds))
+	for _, userId := range userIds {
+		member := &model.GroupMember{
+			GroupId: groupId,
+			UserId:  userId,
+		}
+
+		if result := <-a.Srv.Store.CustomGroup().SaveMember(member); result.Err != nil && result.Err.Id != "store.sql_custom_group.save_member.exists.app_error" {
+			return nil, result.Err
+		}
+
+		members = append(members, member)
+	}
+
+	return members, nil
+}
+
+func (a *App) RemoveCustomGroupMember(groupId string, userId string) *model.AppError {
+	if result := <-a.Srv.Store.CustomGroup().RemoveMember(groupId, userId); result.Err != nil {
+		return result.Err
+	}
+
+	return nil
+}
+
+// AutocompleteCustomGroups returns the groups matching the term with exact matches of the group's
+// name first, followed by groups whose name starts with the term and then by groups whose display
+// name starts with the term.
+func (a *App) AutocompleteCustomGroups(term string) ([]*model.CustomGroup, *model.AppError) {
+	result := <-a.Srv.Store.CustomGroup().Search(term, model.CUSTOM_GROUP_AUTOCOMPLETE_LIMIT)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	groups := result.Data.([]*model.CustomGroup)
+
+	term = strings.ToLower(strings.TrimPrefix(term, "@"))
+	rank := func(group *model.CustomGroup) int {
+		if group.Name == term {
+			return 0
+		} else if strings.HasPrefix(group.Name, term) {
+			return 1
+		}
+
+		return 2
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return rank(groups[i]) < rank(groups[j])
+	})
+
+	return groups, nil
+}
+
+// checkCustomGroupNameAvailable makes sure that a group isn't given the name of an existing user
+// since they'd then be impossible to tell apart in a mention.
+func (a *App) checkCustomGroupNameAvailable(name string) *model.AppError {
+	if result := <-a.Srv.Store.User().GetByUsername(strings.ToLower(name)); result.Err == nil {
+		return model.NewAppError("checkCustomGroupNameAvailable", "app.custom_group.name_taken.app_error", nil, "name="+name, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+// getCustomGroupMentions finds the groups mentioned by the given potential mentions and returns the
+// ids of their members who are in the channel along with the names of the groups that were found.
+func (a *App) getCustomGroupMentions(potentialMentions []string, profileMap map[string]*model.User) (map[string]bool, []string, *model.AppError) {
+	mentionedUserIds := make(map[string]bool)
+	if len(potentialMentions) == 0 {
+		return mentionedUserIds, nil, nil
+	}
+
+	result := <-a.Srv.Store.CustomGroup().GetByNames(potentialMentions)
+	if result.Err != nil {
+		return nil, nil, result.Err
+	}
+	groups := result.Data.([]*model.CustomGroup)
+
+	groupNames := make([]string, 0, len(groups))
+	for _, group := range groups {
+		groupNames = append(groupNames, group.Name)
+
+		mresult := <-a.Srv.Store.CustomGroup().GetMembers(group.Id)
+		if mresult.Err != nil {
+			return nil, nil, mresult.Err
+		}
+
+		for _, member := range mresult.Data.([]*model.GroupMember) {
+			if _, ok := profileMap[member.UserId]; ok {
+				mentionedUserIds[member.UserId] = true
+			}
+		}
+	}
+
+	return mentionedUserIds, groupNames, nil
+}
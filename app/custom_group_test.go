@@ -0,0 +1,146 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func (me *TestHelper) createCustomGroup(name string, displayName string, members ...*model.User) *model.CustomGroup {
+	group, err := me.App.CreateCustomGroup(&model.CustomGroup{
+		Name:        name,
+		DisplayName: displayName,
+	}, me.BasicUser.Id)
+	if err != nil {
+		panic(err)
+	}
+
+	if len(members) > 0 {
+		userIds := make([]string, len(members))
+		for i, member := range members {
+			userIds[i] = member.Id
+		}
+
+		if _, err := me.App.AddCustomGroupMembers(group.Id, userIds); err != nil {
+			panic(err)
+		}
+	}
+
+	return group
+}
+
+func TestCreateCustomGroup(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	group := th.createCustomGroup("g"+model.NewId(), "Group", th.BasicUser2)
+	assert.Equal(t, th.BasicUser.Id, group.CreatorId)
+
+	_, err := th.App.CreateCustomGroup(&model.CustomGroup{Name: th.BasicUser2.Username, DisplayName: "Group"}, th.BasicUser.Id)
+	require.NotNil(t, err)
+	assert.Equal(t, "app.custom_group.name_taken.app_error", err.Id)
+
+	_, err = th.App.PatchCustomGroup(group, &model.CustomGroupPatch{Name: &th.BasicUser.Username})
+	require.NotNil(t, err)
+	assert.Equal(t, "app.custom_group.name_taken.app_error", err.Id)
+
+	// Adding someone twice is fine, but adding a user that doesn't exist isn't
+	_, err = th.App.AddCustomGroupMembers(group.Id, []string{th.BasicUser2.Id, th.BasicUser.Id})
+	require.Nil(t, err)
+
+	_, err = th.App.AddCustomGroupMembers(group.Id, []string{model.NewId()})
+	assert.NotNil(t, err)
+
+	members, err := th.App.GetCustomGroupMembers(group.Id)
+	require.Nil(t, err)
+	assert.Len(t, members, 2)
+}
+
+func TestCustomGroupMentions(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.AddUserToChannel(th.BasicUser2, th.BasicChannel)
+	outsider := th.CreateUser()
+	th.LinkUserToTeam(outsider, th.BasicTeam)
+
+	group := th.createCustomGroup("g"+model.NewId(), "Group", th.BasicUser, th.BasicUser2, outsider)
+
+	t.Run("expands to the members in the channel", func(t *testing.T) {
+		post, err := th.App.CreatePostMissingChannel(&model.Post{
+			UserId:    th.BasicUser.Id,
+			ChannelId: th.BasicChannel.Id,
+			Message:   "hello @" + group.Name,
+		}, true)
+		require.Nil(t, err)
+
+		mentions, err := th.App.SendNotifications(post, th.BasicTeam, th.BasicChannel, th.BasicUser, nil)
+		require.Nil(t, err)
+		assert.Equal(t, []string{th.BasicUser2.Id}, mentions)
+	})
+
+	t.Run("resolves the group's current name", func(t *testing.T) {
+		oldName := group.Name
+		newName := "g" + model.NewId()
+		_, err := th.App.PatchCustomGroup(group, &model.CustomGroupPatch{Name: &newName})
+		require.Nil(t, err)
+
+		post, err := th.App.CreatePostMissingChannel(&model.Post{
+			UserId:    th.BasicUser.Id,
+			ChannelId: th.BasicChannel.Id,
+			Message:   "hello @" + oldName + " and @" + newName,
+		}, true)
+		require.Nil(t, err)
+
+		mentions, err := th.App.SendNotifications(post, th.BasicTeam, th.BasicChannel, th.BasicUser, nil)
+		require.Nil(t, err)
+		assert.Equal(t, []string{th.BasicUser2.Id}, mentions)
+	})
+
+	t.Run("disabled in the channel", func(t *testing.T) {
+		channel := th.BasicChannel
+		disabled := true
+		channel, err := th.App.PatchChannel(channel, &model.ChannelPatch{DisableGroupMentions: &disabled}, th.BasicUser.Id)
+		require.Nil(t, err)
+
+		post, err := th.App.CreatePostMissingChannel(&model.Post{
+			UserId:    th.BasicUser.Id,
+			ChannelId: channel.Id,
+			Message:   "hello @" + group.Name,
+		}, true)
+		require.Nil(t, err)
+
+		mentions, err := th.App.SendNotifications(post, th.BasicTeam, channel, th.BasicUser, nil)
+		require.Nil(t, err)
+		assert.Len(t, mentions, 0)
+	})
+}
+
+func TestAutocompleteCustomGroups(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	prefix := "a" + model.NewId()[:10]
+
+	byDisplayName := th.createCustomGroup("a"+model.NewId(), prefix+" team")
+	longer := th.createCustomGroup(prefix+"-web", "Web")
+	exact := th.createCustomGroup(prefix, "Exact")
+
+	groups, err := th.App.AutocompleteCustomGroups(prefix)
+	require.Nil(t, err)
+	require.Len(t, groups, 3)
+	assert.Equal(t, exact.Id, groups[0].Id)
+	assert.Equal(t, longer.Id, groups[1].Id)
+	assert.Equal(t, byDisplayName.Id, groups[2].Id)
+
+	groups, err = th.App.AutocompleteCustomGroups("@" + prefix + "-")
+	require.Nil(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, longer.Id, groups[0].Id)
+}
@@ -22,6 +22,7 @@ const (
 	TRACK_CONFIG_LOG            = "config_log"
 	TRACK_CONFIG_FILE           = "config_file"
 	TRACK_CONFIG_RATE           = "config_rate"
+	TRACK_CONFIG_CAPTCHA        = "config_captcha"
 	TRACK_CONFIG_EMAIL          = "config_email"
 	TRACK_CONFIG_PRIVACY        = "config_privacy"
 	TRACK_CONFIG_THEME          = "config_theme"
@@ -44,6 +45,8 @@ const (
 	TRACK_CONFIG_MESSAGE_EXPORT = "config_message_export"
 	TRACK_CONFIG_DISPLAY        = "config_display"
 	TRACK_CONFIG_TIMEZONE       = "config_timezone"
+	TRACK_CONFIG_SCIM           = "config_scim"
+	TRACK_CONFIG_FEATURE_FLAGS  = "config_feature_flags"
 
 	TRACK_ACTIVITY = "activity"
 	TRACK_LICENSE  = "license"
@@ -173,13 +176,13 @@ func (a *App) trackActivity() {
 		"registered_users":             userCount,
 		"active_users":                 activeUserCount,
 		"registered_deactivated_users": inactiveUserCount,
-		"teams":                    teamCount,
-		"public_channels":          publicChannelCount,
-		"private_channels":         privateChannelCount,
-		"direct_message_channels":  directChannelCount,
-		"public_channels_deleted":  deletedPublicChannelCount,
-		"private_channels_deleted": deletedPrivateChannelCount,
-		"posts":                    postsCount,
+		"teams":                        teamCount,
+		"public_channels":              publicChannelCount,
+		"private_channels":             privateChannelCount,
+		"direct_message_channels":      directChannelCount,
+		"public_channels_deleted":      deletedPublicChannelCount,
+		"private_channels_deleted":     deletedPrivateChannelCount,
+		"posts":                        postsCount,
 	})
 }
 
@@ -187,6 +190,7 @@ func (a *App) trackConfig() {
 	cfg := a.Config()
 	a.SendDiagnostic(TRACK_CONFIG_SERVICE, map[string]interface{}{
 		"web_server_mode":                                         *cfg.ServiceSettings.WebserverMode,
+		"precompress_static_files":                                *cfg.ServiceSettings.PrecompressStaticFiles,
 		"enable_security_fix_alert":                               *cfg.ServiceSettings.EnableSecurityFixAlert,
 		"enable_insecure_outgoing_connections":                    *cfg.ServiceSettings.EnableInsecureOutgoingConnections,
 		"enable_incoming_webhooks":                                cfg.ServiceSettings.EnableIncomingWebhooks,
@@ -196,6 +200,16 @@ func (a *App) trackConfig() {
 		"enable_post_username_override":                           cfg.ServiceSettings.EnablePostUsernameOverride,
 		"enable_post_icon_override":                               cfg.ServiceSettings.EnablePostIconOverride,
 		"enable_user_access_tokens":                               *cfg.ServiceSettings.EnableUserAccessTokens,
+		"enable_user_blocking":                                    *cfg.ServiceSettings.EnableUserBlocking,
+		"enable_user_impersonation":                               *cfg.ServiceSettings.EnableUserImpersonation,
+		"impersonation_session_length_in_minutes":                 *cfg.ServiceSettings.ImpersonationSessionLengthInMinutes,
+		"enable_local_mode":                                       *cfg.ServiceSettings.EnableLocalMode,
+		"isdefault_local_mode_socket_location":                    isDefault(*cfg.ServiceSettings.LocalModeSocketLocation, model.SERVICE_SETTINGS_DEFAULT_LOCAL_MODE_SOCKET_LOCATION),
+		"enable_preflight_checks":                                 *cfg.ServiceSettings.EnablePreflightChecks,
+		"abort_startup_on_preflight_failure":                      *cfg.ServiceSettings.AbortStartupOnPreflightFailure,
+		"skip_migrations":                                         *cfg.ServiceSettings.SkipMigrations,
+		"config_watch_debounce_milliseconds":                      *cfg.ServiceSettings.ConfigWatchDebounceMilliseconds,
+		"reliable_websocket_events":                               *cfg.ServiceSettings.ReliableWebsocketEvents,
 		"enable_custom_emoji":                                     *cfg.ServiceSettings.EnableCustomEmoji,
 		"enable_emoji_picker":                                     *cfg.ServiceSettings.EnableEmojiPicker,
 		"experimental_enable_authentication_transfer":             *cfg.ServiceSettings.ExperimentalEnableAuthenticationTransfer,
@@ -214,17 +228,73 @@ func (a *App) trackConfig() {
 		"session_length_sso_in_days":                              *cfg.ServiceSettings.SessionLengthSSOInDays,
 		"session_cache_in_minutes":                                *cfg.ServiceSettings.SessionCacheInMinutes,
 		"session_idle_timeout_in_minutes":                         *cfg.ServiceSettings.SessionIdleTimeoutInMinutes,
+		"session_cleanup_interval_minutes":                        *cfg.ServiceSettings.SessionCleanupIntervalMinutes,
+		"session_cleanup_batch_size":                              *cfg.ServiceSettings.SessionCleanupBatchSize,
+		"session_cleanup_retention_days":                          *cfg.ServiceSettings.SessionCleanupRetentionDays,
+		"audit_retention_days":                                    *cfg.ServiceSettings.AuditRetentionDays,
+		"audit_retention_max_rows":                                *cfg.ServiceSettings.AuditRetentionMaxRows,
+		"job_retention_days":                                      *cfg.ServiceSettings.JobRetentionDays,
+		"job_retention_max_rows":                                  *cfg.ServiceSettings.JobRetentionMaxRows,
+		"table_trim_interval_minutes":                             *cfg.ServiceSettings.TableTrimIntervalMinutes,
+		"table_trim_batch_size":                                   *cfg.ServiceSettings.TableTrimBatchSize,
+		"websocket_hubs":                                          *cfg.ServiceSettings.WebsocketHubs,
+		"websocket_hub_rebalance_interval_seconds":                *cfg.ServiceSettings.WebsocketHubRebalanceIntervalSeconds,
+		"websocket_slow_client_policy":                            *cfg.ServiceSettings.WebsocketSlowClientPolicy,
+		"websocket_slow_client_max_drops":                         *cfg.ServiceSettings.WebsocketSlowClientMaxDrops,
+		"websocket_ping_interval_seconds":                         *cfg.ServiceSettings.WebsocketPingIntervalSeconds,
+		"websocket_pong_timeout_seconds":                          *cfg.ServiceSettings.WebsocketPongTimeoutSeconds,
+		"websocket_max_missed_pongs":                              *cfg.ServiceSettings.WebsocketMaxMissedPongs,
+		"websocket_drain_window_seconds":                          *cfg.ServiceSettings.WebsocketDrainWindowSeconds,
+		"enable_presence_subscriptions":                           *cfg.ServiceSettings.EnablePresenceSubscriptions,
+		"max_presence_subscriptions_per_connection":               *cfg.ServiceSettings.MaxPresenceSubscriptionsPerConnection,
+		"post_pipeline_workers":                                   *cfg.ServiceSettings.PostPipelineWorkers,
+		"post_pipeline_queue_size":                                *cfg.ServiceSettings.PostPipelineQueueSize,
+		"post_idempotency_window_seconds":                         *cfg.ServiceSettings.PostIdempotencyWindowSeconds,
+		"post_idempotency_cache_size":                             *cfg.ServiceSettings.PostIdempotencyCacheSize,
+		"enable_api_channel_deletion":                             *cfg.ServiceSettings.EnableAPIChannelDeletion,
+		"channel_deletion_grace_period_days":                      *cfg.ServiceSettings.ChannelDeletionGracePeriodDays,
+		"write_behind_interval_milliseconds":                      *cfg.ServiceSettings.WriteBehindIntervalMilliseconds,
+		"isdefault_trusted_proxy_ip_header":                       isDefault(*cfg.ServiceSettings.TrustedProxyIPHeader, model.SERVICE_SETTINGS_DEFAULT_TRUSTED_PROXY_IP_HEADER),
+		"isdefault_trusted_proxy_cidrs":                           isDefault(*cfg.ServiceSettings.TrustedProxyCIDRs, model.SERVICE_SETTINGS_DEFAULT_TRUSTED_PROXY_CIDRS),
+		"disabled_api_endpoints":                                  len(cfg.ServiceSettings.DisabledAPIEndpoints),
+		"enable_short_permalinks":                                 *cfg.ServiceSettings.EnableShortPermalinks,
+		"short_permalink_expiry_days":                             *cfg.ServiceSettings.ShortPermalinkExpiryDays,
+		"config_history_max_versions":                             *cfg.ServiceSettings.ConfigHistoryMaxVersions,
+		"enable_request_tracing":                                  *cfg.ServiceSettings.EnableRequestTracing,
+		"request_tracing_sample_rate":                             *cfg.ServiceSettings.RequestTracingSampleRate,
+		"request_tracing_slow_threshold_milliseconds":             *cfg.ServiceSettings.RequestTracingSlowThresholdMilliseconds,
 		"isdefault_site_url":                                      isDefault(*cfg.ServiceSettings.SiteURL, model.SERVICE_SETTINGS_DEFAULT_SITE_URL),
 		"isdefault_tls_cert_file":                                 isDefault(*cfg.ServiceSettings.TLSCertFile, model.SERVICE_SETTINGS_DEFAULT_TLS_CERT_FILE),
+		"isdefault_default_timezone":                              isDefault(*cfg.ServiceSettings.DefaultTimezone, model.SERVICE_SETTINGS_DEFAULT_TIMEZONE),
 		"isdefault_tls_key_file":                                  isDefault(*cfg.ServiceSettings.TLSKeyFile, model.SERVICE_SETTINGS_DEFAULT_TLS_KEY_FILE),
 		"isdefault_read_timeout":                                  isDefault(*cfg.ServiceSettings.ReadTimeout, model.SERVICE_SETTINGS_DEFAULT_READ_TIMEOUT),
 		"isdefault_write_timeout":                                 isDefault(*cfg.ServiceSettings.WriteTimeout, model.SERVICE_SETTINGS_DEFAULT_WRITE_TIMEOUT),
 		"isdefault_google_developer_key":                          isDefault(cfg.ServiceSettings.GoogleDeveloperKey, ""),
 		"isdefault_allow_cors_from":                               isDefault(*cfg.ServiceSettings.AllowCorsFrom, model.SERVICE_SETTINGS_DEFAULT_ALLOW_CORS_FROM),
+		"isdefault_cors_allowed_headers":                          isDefault(*cfg.ServiceSettings.CorsAllowedHeaders, ""),
+		"isdefault_cors_exposed_headers":                          isDefault(*cfg.ServiceSettings.CorsExposedHeaders, ""),
+		"cors_allow_credentials":                                  *cfg.ServiceSettings.CorsAllowCredentials,
+		"cors_max_age":                                            *cfg.ServiceSettings.CorsMaxAge,
 		"isdefault_allowed_untrusted_internal_connections":        isDefault(*cfg.ServiceSettings.AllowedUntrustedInternalConnections, ""),
 		"restrict_post_delete":                                    *cfg.ServiceSettings.RestrictPostDelete,
 		"allow_edit_post":                                         *cfg.ServiceSettings.AllowEditPost,
 		"post_edit_time_limit":                                    *cfg.ServiceSettings.PostEditTimeLimit,
+		"post_edit_time_limit_roles":                              len(cfg.ServiceSettings.PostEditTimeLimitByRole),
+		"post_delete_time_limit":                                  *cfg.ServiceSettings.PostDeleteTimeLimit,
+		"post_delete_time_limit_roles":                            len(cfg.ServiceSettings.PostDeleteTimeLimitByRole),
+		"restrict_post_edit_history_to_admins":                    *cfg.ServiceSettings.RestrictPostEditHistoryToAdmins,
+		"unique_emoji_reaction_limit_per_post":                    *cfg.ServiceSettings.UniqueEmojiReactionLimitPerPost,
+		"max_post_props_size":                                     *cfg.ServiceSettings.MaxPostPropsSize,
+		"secret_scanning_mode":                                    *cfg.ServiceSettings.SecretScanningMode,
+		"secret_scanning_rules":                                   len(cfg.ServiceSettings.SecretScanningRules),
+		"secret_scanning_time_budget_milliseconds":                *cfg.ServiceSettings.SecretScanningTimeBudgetMilliseconds,
+		"word_filter_mode":                                        *cfg.ServiceSettings.WordFilterMode,
+		"filtered_words":                                          len(cfg.ServiceSettings.FilteredWords),
+		"word_filter_exempt_system_admins":                        *cfg.ServiceSettings.WordFilterExemptSystemAdmins,
+		"link_blocklist_mode":                                     *cfg.ServiceSettings.LinkBlocklistMode,
+		"enable_channel_feeds":                                    *cfg.ServiceSettings.EnableChannelFeeds,
+		"channel_feed_post_count":                                 *cfg.ServiceSettings.ChannelFeedPostCount,
+		"enable_permalink_previews":                               *cfg.ServiceSettings.EnablePermalinkPreviews,
 		"enable_user_typing_messages":                             *cfg.ServiceSettings.EnableUserTypingMessages,
 		"enable_channel_viewed_messages":                          *cfg.ServiceSettings.EnableChannelViewedMessages,
 		"time_between_user_typing_updates_milliseconds":           *cfg.ServiceSettings.TimeBetweenUserTypingUpdatesMilliseconds,
@@ -235,6 +305,7 @@ func (a *App) trackConfig() {
 		"enable_preview_features":                                 *cfg.ServiceSettings.EnablePreviewFeatures,
 		"enable_tutorial":                                         *cfg.ServiceSettings.EnableTutorial,
 		"experimental_enable_default_channel_leave_join_messages": *cfg.ServiceSettings.ExperimentalEnableDefaultChannelLeaveJoinMessages,
+		"enable_join_leave_messages_by_default":                   *cfg.ServiceSettings.EnableJoinLeaveMessagesByDefault,
 		"experimental_group_unread_channels":                      *cfg.ServiceSettings.ExperimentalGroupUnreadChannels,
 		"isdefault_image_proxy_type":                              isDefault(*cfg.ServiceSettings.ImageProxyType, ""),
 		"isdefault_image_proxy_url":                               isDefault(*cfg.ServiceSettings.ImageProxyURL, ""),
@@ -244,33 +315,40 @@ func (a *App) trackConfig() {
 	})
 
 	a.SendDiagnostic(TRACK_CONFIG_TEAM, map[string]interface{}{
-		"enable_user_creation":                      cfg.TeamSettings.EnableUserCreation,
-		"enable_team_creation":                      *cfg.TeamSettings.EnableTeamCreation,
-		"restrict_team_invite":                      *cfg.TeamSettings.RestrictTeamInvite,
-		"restrict_public_channel_creation":          *cfg.TeamSettings.RestrictPublicChannelCreation,
-		"restrict_private_channel_creation":         *cfg.TeamSettings.RestrictPrivateChannelCreation,
-		"restrict_public_channel_management":        *cfg.TeamSettings.RestrictPublicChannelManagement,
-		"restrict_private_channel_management":       *cfg.TeamSettings.RestrictPrivateChannelManagement,
-		"restrict_public_channel_deletion":          *cfg.TeamSettings.RestrictPublicChannelDeletion,
-		"restrict_private_channel_deletion":         *cfg.TeamSettings.RestrictPrivateChannelDeletion,
-		"enable_open_server":                        *cfg.TeamSettings.EnableOpenServer,
-		"enable_custom_brand":                       *cfg.TeamSettings.EnableCustomBrand,
-		"restrict_direct_message":                   *cfg.TeamSettings.RestrictDirectMessage,
-		"max_notifications_per_channel":             *cfg.TeamSettings.MaxNotificationsPerChannel,
-		"enable_confirm_notifications_to_channel":   *cfg.TeamSettings.EnableConfirmNotificationsToChannel,
-		"max_users_per_team":                        *cfg.TeamSettings.MaxUsersPerTeam,
-		"max_channels_per_team":                     *cfg.TeamSettings.MaxChannelsPerTeam,
-		"teammate_name_display":                     *cfg.TeamSettings.TeammateNameDisplay,
-		"isdefault_site_name":                       isDefault(cfg.TeamSettings.SiteName, "Mattermost"),
-		"isdefault_custom_brand_text":               isDefault(*cfg.TeamSettings.CustomBrandText, model.TEAM_SETTINGS_DEFAULT_CUSTOM_BRAND_TEXT),
-		"isdefault_custom_description_text":         isDefault(*cfg.TeamSettings.CustomDescriptionText, model.TEAM_SETTINGS_DEFAULT_CUSTOM_DESCRIPTION_TEXT),
-		"isdefault_user_status_away_timeout":        isDefault(*cfg.TeamSettings.UserStatusAwayTimeout, model.TEAM_SETTINGS_DEFAULT_USER_STATUS_AWAY_TIMEOUT),
-		"restrict_private_channel_manage_members":   *cfg.TeamSettings.RestrictPrivateChannelManageMembers,
-		"enable_X_to_leave_channels_from_LHS":       *cfg.TeamSettings.EnableXToLeaveChannelsFromLHS,
-		"experimental_enable_automatic_replies":     *cfg.TeamSettings.ExperimentalEnableAutomaticReplies,
-		"experimental_town_square_is_hidden_in_lhs": *cfg.TeamSettings.ExperimentalHideTownSquareinLHS,
-		"experimental_town_square_is_read_only":     *cfg.TeamSettings.ExperimentalTownSquareIsReadOnly,
-		"experimental_primary_team":                 isDefault(*cfg.TeamSettings.ExperimentalPrimaryTeam, ""),
+		"enable_user_creation":                        cfg.TeamSettings.EnableUserCreation,
+		"enable_team_creation":                        *cfg.TeamSettings.EnableTeamCreation,
+		"restrict_team_invite":                        *cfg.TeamSettings.RestrictTeamInvite,
+		"restrict_public_channel_creation":            *cfg.TeamSettings.RestrictPublicChannelCreation,
+		"restrict_private_channel_creation":           *cfg.TeamSettings.RestrictPrivateChannelCreation,
+		"restrict_public_channel_management":          *cfg.TeamSettings.RestrictPublicChannelManagement,
+		"restrict_private_channel_management":         *cfg.TeamSettings.RestrictPrivateChannelManagement,
+		"restrict_public_channel_deletion":            *cfg.TeamSettings.RestrictPublicChannelDeletion,
+		"restrict_private_channel_deletion":           *cfg.TeamSettings.RestrictPrivateChannelDeletion,
+		"enable_open_server":                          *cfg.TeamSettings.EnableOpenServer,
+		"enable_custom_brand":                         *cfg.TeamSettings.EnableCustomBrand,
+		"restrict_direct_message":                     *cfg.TeamSettings.RestrictDirectMessage,
+		"max_notifications_per_channel":               *cfg.TeamSettings.MaxNotificationsPerChannel,
+		"enable_confirm_notifications_to_channel":     *cfg.TeamSettings.EnableConfirmNotificationsToChannel,
+		"enable_private_to_public_conversion":         *cfg.TeamSettings.EnablePrivateToPublicConversion,
+		"max_users_per_team":                          *cfg.TeamSettings.MaxUsersPerTeam,
+		"max_channels_per_team":                       *cfg.TeamSettings.MaxChannelsPerTeam,
+		"isdefault_max_channel_header_length":         isDefault(*cfg.TeamSettings.MaxChannelHeaderLength, model.TEAM_SETTINGS_DEFAULT_MAX_CHANNEL_HEADER),
+		"isdefault_max_channel_purpose_length":        isDefault(*cfg.TeamSettings.MaxChannelPurposeLength, model.TEAM_SETTINGS_DEFAULT_MAX_CHANNEL_PURPOSE),
+		"teammate_name_display":                       *cfg.TeamSettings.TeammateNameDisplay,
+		"isdefault_site_name":                         isDefault(cfg.TeamSettings.SiteName, "Mattermost"),
+		"isdefault_custom_brand_text":                 isDefault(*cfg.TeamSettings.CustomBrandText, model.TEAM_SETTINGS_DEFAULT_CUSTOM_BRAND_TEXT),
+		"isdefault_custom_description_text":           isDefault(*cfg.TeamSettings.CustomDescriptionText, model.TEAM_SETTINGS_DEFAULT_CUSTOM_DESCRIPTION_TEXT),
+		"isdefault_user_status_away_timeout":          isDefault(*cfg.TeamSettings.UserStatusAwayTimeout, model.TEAM_SETTINGS_DEFAULT_USER_STATUS_AWAY_TIMEOUT),
+		"restrict_private_channel_manage_members":     *cfg.TeamSettings.RestrictPrivateChannelManageMembers,
+		"restrict_group_channel_manage_members":       *cfg.TeamSettings.RestrictGroupChannelManageMembers,
+		"enable_group_channel_removed_member_history": *cfg.TeamSettings.EnableGroupChannelRemovedMemberHistory,
+		"default_channel_desktop_notify_prop":         cfg.TeamSettings.DefaultChannelNotifyProps[model.DESKTOP_NOTIFY_PROP],
+		"default_channel_push_notify_prop":            cfg.TeamSettings.DefaultChannelNotifyProps[model.PUSH_NOTIFY_PROP],
+		"enable_X_to_leave_channels_from_LHS":         *cfg.TeamSettings.EnableXToLeaveChannelsFromLHS,
+		"experimental_enable_automatic_replies":       *cfg.TeamSettings.ExperimentalEnableAutomaticReplies,
+		"experimental_town_square_is_hidden_in_lhs":   *cfg.TeamSettings.ExperimentalHideTownSquareinLHS,
+		"experimental_town_square_is_read_only":       *cfg.TeamSettings.ExperimentalTownSquareIsReadOnly,
+		"experimental_primary_team":                   isDefault(*cfg.TeamSettings.ExperimentalPrimaryTeam, ""),
 	})
 
 	a.SendDiagnostic(TRACK_CONFIG_CLIENT_REQ, map[string]interface{}{
@@ -290,6 +368,8 @@ func (a *App) trackConfig() {
 		"data_source_replicas":        len(cfg.SqlSettings.DataSourceReplicas),
 		"data_source_search_replicas": len(cfg.SqlSettings.DataSourceSearchReplicas),
 		"query_timeout":               *cfg.SqlSettings.QueryTimeout,
+		"data_source_failover":        *cfg.SqlSettings.DataSourceFailover != "",
+		"health_check_interval":       *cfg.SqlSettings.HealthCheckIntervalSeconds,
 	})
 
 	a.SendDiagnostic(TRACK_CONFIG_LOG, map[string]interface{}{
@@ -312,16 +392,23 @@ func (a *App) trackConfig() {
 	})
 
 	a.SendDiagnostic(TRACK_CONFIG_FILE, map[string]interface{}{
-		"enable_public_links":     cfg.FileSettings.EnablePublicLink,
-		"driver_name":             *cfg.FileSettings.DriverName,
-		"amazon_s3_ssl":           *cfg.FileSettings.AmazonS3SSL,
-		"amazon_s3_sse":           *cfg.FileSettings.AmazonS3SSE,
-		"amazon_s3_signv2":        *cfg.FileSettings.AmazonS3SignV2,
-		"amazon_s3_trace":         *cfg.FileSettings.AmazonS3Trace,
-		"max_file_size":           *cfg.FileSettings.MaxFileSize,
-		"enable_file_attachments": *cfg.FileSettings.EnableFileAttachments,
-		"enable_mobile_upload":    *cfg.FileSettings.EnableMobileUpload,
-		"enable_mobile_download":  *cfg.FileSettings.EnableMobileDownload,
+		"enable_public_links":           cfg.FileSettings.EnablePublicLink,
+		"enable_legacy_public_links":    *cfg.FileSettings.EnableLegacyPublicLinks,
+		"driver_name":                   *cfg.FileSettings.DriverName,
+		"amazon_s3_ssl":                 *cfg.FileSettings.AmazonS3SSL,
+		"amazon_s3_sse":                 *cfg.FileSettings.AmazonS3SSE,
+		"amazon_s3_signv2":              *cfg.FileSettings.AmazonS3SignV2,
+		"amazon_s3_trace":               *cfg.FileSettings.AmazonS3Trace,
+		"max_file_size":                 *cfg.FileSettings.MaxFileSize,
+		"max_file_attachments":          *cfg.FileSettings.MaxFileAttachments,
+		"user_storage_quota":            *cfg.FileSettings.UserStorageQuota,
+		"team_storage_quota":            *cfg.FileSettings.TeamStorageQuota,
+		"enable_file_attachments":       *cfg.FileSettings.EnableFileAttachments,
+		"enable_mobile_upload":          *cfg.FileSettings.EnableMobileUpload,
+		"enable_mobile_download":        *cfg.FileSettings.EnableMobileDownload,
+		"inline_content_types":          len(cfg.FileSettings.InlineContentTypes),
+		"enable_profile_image_initials": *cfg.FileSettings.EnableProfileImageInitials,
+		"deduplicate_across_users":      *cfg.FileSettings.DeduplicateAcrossUsers,
 	})
 
 	a.SendDiagnostic(TRACK_CONFIG_EMAIL, map[string]interface{}{
@@ -347,6 +434,8 @@ func (a *App) trackConfig() {
 		"isdefault_login_button_color":         isDefault(*cfg.EmailSettings.LoginButtonColor, ""),
 		"isdefault_login_button_border_color":  isDefault(*cfg.EmailSettings.LoginButtonBorderColor, ""),
 		"isdefault_login_button_text_color":    isDefault(*cfg.EmailSettings.LoginButtonTextColor, ""),
+		"queued_emails_per_minute":             *cfg.EmailSettings.QueuedEmailsPerMinute,
+		"enable_bounce_webhook":                *cfg.EmailSettings.BounceWebhookSecret != "",
 	})
 
 	a.SendDiagnostic(TRACK_CONFIG_RATE, map[string]interface{}{
@@ -359,6 +448,16 @@ func (a *App) trackConfig() {
 		"isdefault_vary_by_header": isDefault(cfg.RateLimitSettings.VaryByHeader, ""),
 	})
 
+	a.SendDiagnostic(TRACK_CONFIG_CAPTCHA, map[string]interface{}{
+		"enable":                  *cfg.CaptchaSettings.Enable,
+		"provider":                *cfg.CaptchaSettings.Provider,
+		"isdefault_verify_url":    isDefault(*cfg.CaptchaSettings.VerifyURL, model.CAPTCHA_SETTINGS_DEFAULT_VERIFY_URL),
+		"verify_timeout_seconds":  *cfg.CaptchaSettings.VerifyTimeoutSeconds,
+		"login_failures_per_ip":   *cfg.CaptchaSettings.LoginFailuresPerIp,
+		"login_failures_per_user": *cfg.CaptchaSettings.LoginFailuresPerUser,
+		"failure_window_minutes":  *cfg.CaptchaSettings.FailureWindowMinutes,
+	})
+
 	a.SendDiagnostic(TRACK_CONFIG_PRIVACY, map[string]interface{}{
 		"show_email_address": cfg.PrivacySettings.ShowEmailAddress,
 		"show_full_name":     cfg.PrivacySettings.ShowFullName,
@@ -384,6 +483,7 @@ func (a *App) trackConfig() {
 		"isdefault_help_link":             isDefault(*cfg.SupportSettings.HelpLink, model.SUPPORT_SETTINGS_DEFAULT_HELP_LINK),
 		"isdefault_report_a_problem_link": isDefault(*cfg.SupportSettings.ReportAProblemLink, model.SUPPORT_SETTINGS_DEFAULT_REPORT_A_PROBLEM_LINK),
 		"isdefault_support_email":         isDefault(*cfg.SupportSettings.SupportEmail, model.SUPPORT_SETTINGS_DEFAULT_SUPPORT_EMAIL),
+		"custom_terms_of_service_enabled": *cfg.SupportSettings.CustomTermsOfServiceEnabled,
 	})
 
 	a.SendDiagnostic(TRACK_CONFIG_LDAP, map[string]interface{}{
@@ -464,6 +564,8 @@ func (a *App) trackConfig() {
 
 	a.SendDiagnostic(TRACK_CONFIG_ANALYTICS, map[string]interface{}{
 		"isdefault_max_users_for_statistics": isDefault(*cfg.AnalyticsSettings.MaxUsersForStatistics, model.ANALYTICS_SETTINGS_DEFAULT_MAX_USERS_FOR_STATISTICS),
+		"daily_retention_days":               *cfg.AnalyticsSettings.DailyRetentionDays,
+		"minimum_user_activity":              *cfg.AnalyticsSettings.MinimumUserActivity,
 	})
 
 	a.SendDiagnostic(TRACK_CONFIG_ANNOUNCEMENT, map[string]interface{}{
@@ -471,6 +573,8 @@ func (a *App) trackConfig() {
 		"isdefault_banner_color":      isDefault(*cfg.AnnouncementSettings.BannerColor, model.ANNOUNCEMENT_SETTINGS_DEFAULT_BANNER_COLOR),
 		"isdefault_banner_text_color": isDefault(*cfg.AnnouncementSettings.BannerTextColor, model.ANNOUNCEMENT_SETTINGS_DEFAULT_BANNER_TEXT_COLOR),
 		"allow_banner_dismissal":      *cfg.AnnouncementSettings.AllowBannerDismissal,
+		"enable_welcome_message":      *cfg.AnnouncementSettings.EnableWelcomeMessage,
+		"isdefault_welcome_message":   isDefault(*cfg.AnnouncementSettings.WelcomeMessage, model.ANNOUNCEMENT_SETTINGS_DEFAULT_WELCOME_MESSAGE),
 	})
 
 	a.SendDiagnostic(TRACK_CONFIG_ELASTICSEARCH, map[string]interface{}{
@@ -489,10 +593,13 @@ func (a *App) trackConfig() {
 	})
 
 	a.SendDiagnostic(TRACK_CONFIG_PLUGIN, map[string]interface{}{
-		"enable_jira":    pluginSetting(&cfg.PluginSettings, "jira", "enabled", false),
-		"enable_zoom":    pluginActivated(cfg.PluginSettings.PluginStates, "zoom"),
-		"enable":         *cfg.PluginSettings.Enable,
-		"enable_uploads": *cfg.PluginSettings.EnableUploads,
+		"enable_jira":                      pluginSetting(&cfg.PluginSettings, "jira", "enabled", false),
+		"enable_zoom":                      pluginActivated(cfg.PluginSettings.PluginStates, "zoom"),
+		"enable":                           *cfg.PluginSettings.Enable,
+		"enable_uploads":                   *cfg.PluginSettings.EnableUploads,
+		"install_from_url_max_file_size":   *cfg.PluginSettings.InstallFromURLMaxFileSize,
+		"install_from_url_timeout_seconds": *cfg.PluginSettings.InstallFromURLTimeoutSeconds,
+		"isdefault_install_from_url_ca_certificate_file": isDefault(*cfg.PluginSettings.InstallFromURLCACertificateFile, ""),
 	})
 
 	a.SendDiagnostic(TRACK_CONFIG_DATA_RETENTION, map[string]interface{}{
@@ -522,6 +629,18 @@ func (a *App) trackConfig() {
 	a.SendDiagnostic(TRACK_CONFIG_TIMEZONE, map[string]interface{}{
 		"isdefault_supported_timezones_path": isDefault(*cfg.TimezoneSettings.SupportedTimezonesPath, model.TIMEZONE_SETTINGS_DEFAULT_SUPPORTED_TIMEZONES_PATH),
 	})
+
+	a.SendDiagnostic(TRACK_CONFIG_SCIM, map[string]interface{}{
+		"enable":            *cfg.ScimSettings.Enable,
+		"user_auth_service": *cfg.ScimSettings.UserAuthService,
+		"group_mapping":     *cfg.ScimSettings.GroupMapping,
+	})
+
+	featureFlags := map[string]interface{}{}
+	for name, value := range cfg.FeatureFlags.ToMap() {
+		featureFlags[name] = value
+	}
+	a.SendDiagnostic(TRACK_CONFIG_FEATURE_FLAGS, featureFlags)
 }
 
 func (a *App) trackLicense() {
@@ -0,0 +1,125 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+type apiRoute struct {
+	methods  []string
+	segments []string
+}
+
+// apiRoutes returns the routes that have been added to the server's router, split into the parts of their paths.
+func (a *App) apiRoutes() []apiRoute {
+	var routes []apiRoute
+
+	a.Srv.Router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		// Routes without handlers are the path prefixes that other routes are added under
+		if route.GetHandler() == nil {
+			return nil
+		}
+
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+
+		// Routes without methods can be requested with any of them
+		methods, _ := route.GetMethods()
+
+		routes = append(routes, apiRoute{
+			methods:  methods,
+			segments: strings.Split(strings.TrimRight(template, "/"), "/"),
+		})
+		return nil
+	})
+
+	return routes
+}
+
+// hasPrefix returns true if the route can be requested with the method, or any method for "*", at a path that's below
+// prefix.
+func (route apiRoute) hasPrefix(method string, prefix []string) bool {
+	if len(prefix) > len(route.segments) {
+		return false
+	}
+
+	if method != "*" && len(route.methods) > 0 {
+		allowed := false
+		for _, routeMethod := range route.methods {
+			if routeMethod == method {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+
+	for i, segment := range prefix {
+		template := route.segments[i]
+		if !strings.HasPrefix(template, "{") || !strings.HasSuffix(template, "}") {
+			if segment != template {
+				return false
+			}
+			continue
+		}
+
+		// Variables without a pattern of their own match anything
+		pattern := "[^/]+"
+		if colon := strings.Index(template, ":"); colon != -1 {
+			pattern = template[colon+1 : len(template)-1]
+		}
+		if matched, err := regexp.MatchString("^(?:"+pattern+")$", segment); err != nil || !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ValidateDisabledAPIEndpoints checks that each of the endpoints disabled by a config is the prefix of a route that's
+// been added to the server's router, so that a typo doesn't leave the endpoint that was meant to be disabled enabled.
+// Nothing is checked until the routes have been added.
+func (a *App) ValidateDisabledAPIEndpoints(cfg *model.Config) *model.AppError {
+	if len(cfg.ServiceSettings.DisabledAPIEndpoints) == 0 {
+		return nil
+	}
+
+	routes := a.apiRoutes()
+	if len(routes) == 0 {
+		return nil
+	}
+
+	for _, endpoint := range cfg.ServiceSettings.DisabledAPIEndpoints {
+		method, path, ok := model.ParseDisabledAPIEndpoint(endpoint)
+		if !ok {
+			return model.NewAppError("ValidateDisabledAPIEndpoints", "model.config.is_valid.disabled_api_endpoint.app_error", map[string]interface{}{"Endpoint": endpoint}, "", http.StatusBadRequest)
+		}
+
+		prefix := strings.Split(path, "/")
+		found := false
+		for _, route := range routes {
+			if route.hasPrefix(method, prefix) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return model.NewAppError("ValidateDisabledAPIEndpoints", "app.disabled_api_endpoints.unknown.app_error", map[string]interface{}{"Endpoint": endpoint}, "", http.StatusBadRequest)
+		}
+	}
+
+	return nil
+}
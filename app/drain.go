@@ -0,0 +1,125 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// StartDrain starts draining the server so that it can be stopped without its users noticing. New websocket
+// connections are refused from then on so that the load balancer sends them to another server, the users that are
+// already connected are told to reconnect a few at a time over ServiceSettings.WebsocketDrainWindowSeconds, and the
+// jobs that are running are left to finish before no more are run. It returns the status of the drain, which is
+// already underway if the server was draining.
+//
+// The server keeps draining until it's restarted.
+func (a *App) StartDrain() *model.DrainStatus {
+	a.drainLock.Lock()
+	defer a.drainLock.Unlock()
+
+	if a.drainStatus != nil {
+		return a.getDrainStatus()
+	}
+
+	userIds := a.hubRegistry.users()
+	window := *a.Config().ServiceSettings.WebsocketDrainWindowSeconds
+
+	a.drainStatus = &model.DrainStatus{
+		Draining:      true,
+		StartedAt:     model.GetMillis(),
+		WindowSeconds: window,
+		Users:         len(userIds),
+	}
+	a.drainStop = make(chan struct{})
+
+	mlog.Info(fmt.Sprintf("Draining the server, telling %v connected users to reconnect over %v seconds", len(userIds), window))
+
+	stop := a.drainStop
+	a.Go(func() {
+		a.notifyUsersToReconnect(userIds, time.Duration(window)*time.Second, stop)
+	})
+	a.Go(func() {
+		a.Jobs.StopWorkers()
+
+		a.drainLock.Lock()
+		a.drainStatus.JobsFinished = true
+		a.drainLock.Unlock()
+
+		mlog.Info("Jobs finished running on the draining server")
+	})
+
+	return a.getDrainStatus()
+}
+
+// notifyUsersToReconnect sends each of the users a reconnect event, spaced out evenly over the window so that the
+// other servers aren't sent everyone at once. Their connections are closed once they've been sent it.
+func (a *App) notifyUsersToReconnect(userIds []string, window time.Duration, stop chan struct{}) {
+	var interval time.Duration
+	if len(userIds) > 0 {
+		interval = window / time.Duration(len(userIds))
+	}
+
+	for i, userId := range userIds {
+		if i > 0 && interval > 0 {
+			timer := time.NewTimer(interval)
+			select {
+			case <-timer.C:
+			case <-stop:
+				timer.Stop()
+				return
+			}
+		}
+
+		a.PublishSkipClusterSend(model.NewWebSocketEvent(model.WEBSOCKET_EVENT_RECONNECT, "", "", userId, nil))
+
+		a.drainLock.Lock()
+		a.drainStatus.UsersNotified++
+		a.drainLock.Unlock()
+	}
+
+	mlog.Info("Every user connected to the draining server has been told to reconnect")
+}
+
+// GetDrainStatus returns how far along draining the server is. The status isn't draining if it hasn't been started.
+func (a *App) GetDrainStatus() *model.DrainStatus {
+	a.drainLock.Lock()
+	defer a.drainLock.Unlock()
+
+	return a.getDrainStatus()
+}
+
+func (a *App) getDrainStatus() *model.DrainStatus {
+	status := &model.DrainStatus{}
+	if a.drainStatus != nil {
+		*status = *a.drainStatus
+	}
+
+	status.WebSocketConnections = a.TotalWebsocketConnections()
+	status.Done = status.Draining && status.UsersNotified == status.Users && status.JobsFinished
+
+	return status
+}
+
+// IsDraining returns whether the server has started draining.
+func (a *App) IsDraining() bool {
+	a.drainLock.Lock()
+	defer a.drainLock.Unlock()
+
+	return a.drainStatus != nil
+}
+
+// stopDrain stops telling users to reconnect, since every connection is about to be closed anyway.
+func (a *App) stopDrain() {
+	a.drainLock.Lock()
+	defer a.drainLock.Unlock()
+
+	if a.drainStop != nil {
+		close(a.drainStop)
+		a.drainStop = nil
+	}
+}
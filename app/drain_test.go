@@ -0,0 +1,99 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// waitForReconnect sends how long after start the connection was sent a reconnect event, or -1 if it isn't sent one
+// within the timeout.
+func waitForReconnect(wc *WebConn, start time.Time, timeout time.Duration, received chan<- time.Duration) {
+	deadline := time.After(timeout)
+	for {
+		select {
+		case msg, ok := <-wc.Send:
+			if !ok {
+				received <- -1
+				return
+			}
+
+			if evt, ok := msg.(*model.WebSocketEvent); ok && evt.Event == model.WEBSOCKET_EVENT_RECONNECT {
+				received <- time.Since(start)
+				return
+			}
+		case <-deadline:
+			received <- -1
+			return
+		}
+	}
+}
+
+func TestStartDrain(t *testing.T) {
+	th := Setup()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.WebsocketDrainWindowSeconds = 1 })
+
+	assert.False(t, th.App.IsDraining())
+	assert.False(t, th.App.GetDrainStatus().Draining)
+
+	var conns []*WebConn
+	for i := 0; i < 3; i++ {
+		wc := newTestHubWebConn(th.App, model.NewId())
+		th.App.HubRegister(wc)
+		conns = append(conns, wc)
+	}
+	defer func() {
+		for _, wc := range conns {
+			th.App.HubUnregister(wc)
+		}
+	}()
+
+	start := time.Now()
+	received := make(chan time.Duration, len(conns))
+	for _, wc := range conns {
+		go waitForReconnect(wc, start, 5*time.Second, received)
+	}
+
+	status := th.App.StartDrain()
+	assert.True(t, status.Draining)
+	assert.Equal(t, 1, status.WindowSeconds)
+	assert.Equal(t, 3, status.Users)
+	assert.True(t, th.App.IsDraining())
+
+	var delays []time.Duration
+	for range conns {
+		delay := <-received
+		require.True(t, delay >= 0, "every user should have been told to reconnect")
+		delays = append(delays, delay)
+	}
+	sort.Slice(delays, func(i, j int) bool { return delays[i] < delays[j] })
+
+	// The users are spread out over the window, a third of a second apart
+	assert.True(t, delays[0] < 300*time.Millisecond, "the first user should be told straight away, not after %v", delays[0])
+	assert.True(t, delays[2]-delays[0] >= 500*time.Millisecond, "the users should be told over the window, not within %v", delays[2]-delays[0])
+
+	for start := time.Now(); !th.App.GetDrainStatus().Done; time.Sleep(10 * time.Millisecond) {
+		require.True(t, time.Since(start) < 5*time.Second, "the drain didn't finish")
+	}
+
+	status = th.App.GetDrainStatus()
+	assert.Equal(t, 3, status.UsersNotified)
+	assert.True(t, status.JobsFinished)
+
+	t.Run("draining again keeps the drain that's underway", func(t *testing.T) {
+		again := th.App.StartDrain()
+		assert.Equal(t, status.StartedAt, again.StartedAt)
+		assert.Equal(t, 3, again.Users)
+		assert.True(t, again.Done)
+	})
+}
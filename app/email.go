@@ -8,6 +8,8 @@ import (
 	"net/url"
 
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/nicksnyder/go-i18n/i18n"
 
@@ -80,6 +82,31 @@ func (a *App) SendEmailChangeEmail(oldEmail, newEmail, locale, siteURL string) *
 	return nil
 }
 
+// SendEmailChangePendingEmail tells a user at their current address that a change to their email address is waiting
+// to be verified, giving them a link to undo it if they didn't make it.
+func (a *App) SendEmailChangePendingEmail(oldEmail, newEmail, locale, siteURL, token string) *model.AppError {
+	T := utils.GetUserTranslations(locale)
+
+	link := fmt.Sprintf("%s%s?token=%s", siteURL, EMAIL_CHANGE_UNDO_PATH, url.QueryEscape(token))
+
+	subject := T("api.templates.email_change_pending_subject",
+		map[string]interface{}{"SiteName": a.ClientConfig()["SiteName"]})
+
+	bodyPage := a.NewEmailTemplate("email_change_pending_body", locale)
+	bodyPage.Props["SiteURL"] = siteURL
+	bodyPage.Props["Title"] = T("api.templates.email_change_pending_body.title")
+	bodyPage.Html["Info"] = utils.TranslateAsHtml(T, "api.templates.email_change_pending_body.info",
+		map[string]interface{}{"TeamDisplayName": a.Config().TeamSettings.SiteName, "NewEmail": newEmail})
+	bodyPage.Props["UndoUrl"] = link
+	bodyPage.Props["UndoButton"] = T("api.templates.email_change_pending_body.button")
+
+	if err := a.SendMail(oldEmail, subject, bodyPage.Render()); err != nil {
+		return model.NewAppError("SendEmailChangePendingEmail", "api.user.send_email_change_email_and_forget.error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
 func (a *App) SendVerifyEmail(userEmail, locale, siteURL, token string) *model.AppError {
 	T := utils.GetUserTranslations(locale)
 
@@ -123,6 +150,50 @@ func (a *App) SendSignInChangeEmail(email, method, locale, siteURL string) *mode
 	return nil
 }
 
+func (a *App) SendSessionAnomalyEmail(email, agent, ipAddress string, revoked bool, locale, siteURL string) *model.AppError {
+	T := utils.GetUserTranslations(locale)
+
+	subject := T("api.templates.session_anomaly.subject",
+		map[string]interface{}{"SiteName": a.ClientConfig()["SiteName"]})
+
+	info := "api.templates.session_anomaly.info"
+	if revoked {
+		info = "api.templates.session_anomaly.info_revoked"
+	}
+
+	bodyPage := a.NewEmailTemplate("signin_change_body", locale)
+	bodyPage.Props["SiteURL"] = siteURL
+	bodyPage.Props["Title"] = T("api.templates.session_anomaly.title")
+	bodyPage.Html["Info"] = utils.TranslateAsHtml(T, info,
+		map[string]interface{}{"SiteName": a.ClientConfig()["SiteName"], "Agent": agent, "IpAddress": ipAddress})
+
+	if err := a.SendMail(email, subject, bodyPage.Render()); err != nil {
+		return model.NewAppError("SendSessionAnomalyEmail", "api.user.send_session_anomaly_email.error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+func (a *App) SendIdleDeactivationWarningEmail(email string, idleDays int, deactivateAt time.Time, locale, siteURL string) *model.AppError {
+	T := utils.GetUserTranslations(locale)
+	t := getFormattedTime(deactivateAt, T)
+
+	subject := T("api.templates.idle_deactivation_warning.subject",
+		map[string]interface{}{"SiteName": a.ClientConfig()["SiteName"]})
+
+	bodyPage := a.NewEmailTemplate("signin_change_body", locale)
+	bodyPage.Props["SiteURL"] = siteURL
+	bodyPage.Props["Title"] = T("api.templates.idle_deactivation_warning.title")
+	bodyPage.Html["Info"] = utils.TranslateAsHtml(T, "api.templates.idle_deactivation_warning.info",
+		map[string]interface{}{"SiteName": a.ClientConfig()["SiteName"], "SiteURL": siteURL, "IdleDays": idleDays, "Month": t.Month, "Day": t.Day, "Year": t.Year})
+
+	if err := a.SendMail(email, subject, bodyPage.Render()); err != nil {
+		return model.NewAppError("SendIdleDeactivationWarningEmail", "api.user.send_idle_deactivation_warning_email.error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
 func (a *App) SendWelcomeEmail(userId string, email string, verified bool, locale, siteURL string) *model.AppError {
 	T := utils.GetUserTranslations(locale)
 
@@ -162,7 +233,7 @@ func (a *App) SendWelcomeEmail(userId string, email string, verified bool, local
 	return nil
 }
 
-func (a *App) SendPasswordChangeEmail(email, method, locale, siteURL string) *model.AppError {
+func (a *App) SendPasswordChangeEmail(email, method, locale, siteURL string, location *time.Location) *model.AppError {
 	T := utils.GetUserTranslations(locale)
 
 	subject := T("api.templates.password_change_subject",
@@ -174,6 +245,7 @@ func (a *App) SendPasswordChangeEmail(email, method, locale, siteURL string) *mo
 	bodyPage.Props["Title"] = T("api.templates.password_change_body.title")
 	bodyPage.Html["Info"] = utils.TranslateAsHtml(T, "api.templates.password_change_body.info",
 		map[string]interface{}{"TeamDisplayName": a.Config().TeamSettings.SiteName, "TeamURL": siteURL, "Method": method})
+	bodyPage.Props["ChangeTime"] = securityChangeTime(T, location)
 
 	if err := a.SendMail(email, subject, bodyPage.Render()); err != nil {
 		return model.NewAppError("SendPasswordChangeEmail", "api.user.send_password_change_email_and_forget.error", nil, err.Error(), http.StatusInternalServerError)
@@ -182,7 +254,7 @@ func (a *App) SendPasswordChangeEmail(email, method, locale, siteURL string) *mo
 	return nil
 }
 
-func (a *App) SendUserAccessTokenAddedEmail(email, locale string) *model.AppError {
+func (a *App) SendUserAccessTokenAddedEmail(email, locale string, location *time.Location) *model.AppError {
 	T := utils.GetUserTranslations(locale)
 
 	subject := T("api.templates.user_access_token_subject",
@@ -192,6 +264,7 @@ func (a *App) SendUserAccessTokenAddedEmail(email, locale string) *model.AppErro
 	bodyPage.Props["Title"] = T("api.templates.user_access_token_body.title")
 	bodyPage.Html["Info"] = utils.TranslateAsHtml(T, "api.templates.user_access_token_body.info",
 		map[string]interface{}{"SiteName": a.ClientConfig()["SiteName"], "SiteURL": a.GetSiteURL()})
+	bodyPage.Props["ChangeTime"] = securityChangeTime(T, location)
 
 	if err := a.SendMail(email, subject, bodyPage.Render()); err != nil {
 		return model.NewAppError("SendUserAccessTokenAddedEmail", "api.user.send_user_access_token.error", nil, err.Error(), http.StatusInternalServerError)
@@ -223,7 +296,7 @@ func (a *App) SendPasswordResetEmail(email string, token *model.Token, locale, s
 	return true, nil
 }
 
-func (a *App) SendMfaChangeEmail(email string, activated bool, locale, siteURL string) *model.AppError {
+func (a *App) SendMfaChangeEmail(email string, activated bool, locale, siteURL string, location *time.Location) *model.AppError {
 	T := utils.GetUserTranslations(locale)
 
 	subject := T("api.templates.mfa_change_subject",
@@ -242,6 +315,7 @@ func (a *App) SendMfaChangeEmail(email string, activated bool, locale, siteURL s
 	}
 
 	bodyPage.Html["Info"] = utils.TranslateAsHtml(T, bodyText, map[string]interface{}{"SiteURL": siteURL})
+	bodyPage.Props["ChangeTime"] = securityChangeTime(T, location)
 
 	if err := a.SendMail(email, subject, bodyPage.Render()); err != nil {
 		return model.NewAppError("SendMfaChangeEmail", "api.user.send_mfa_change_email.error", nil, err.Error(), http.StatusInternalServerError)
@@ -298,6 +372,141 @@ func (a *App) SendInviteEmails(team *model.Team, senderName string, invites []st
 	}
 }
 
+func (a *App) SendGuestInviteEmails(team *model.Team, channels []*model.Channel, senderName string, invites []string, siteURL string, message string) {
+	channelIds := make([]string, 0, len(channels))
+	channelNames := make([]string, 0, len(channels))
+	for _, channel := range channels {
+		channelIds = append(channelIds, channel.Id)
+		channelNames = append(channelNames, channel.DisplayName)
+	}
+
+	for _, invite := range invites {
+		if len(invite) > 0 {
+			subject := utils.T("api.templates.invite_guest_subject",
+				map[string]interface{}{"SenderName": senderName,
+					"TeamDisplayName": team.DisplayName,
+					"SiteName":        a.ClientConfig()["SiteName"]})
+
+			bodyPage := a.NewEmailTemplate("invite_body", model.DEFAULT_LOCALE)
+			bodyPage.Props["SiteURL"] = siteURL
+			bodyPage.Props["Title"] = utils.T("api.templates.invite_body.title")
+			bodyPage.Html["Info"] = utils.TranslateAsHtml(utils.T, "api.templates.invite_guest_body.info",
+				map[string]interface{}{"SenderName": senderName, "TeamDisplayName": team.DisplayName, "ChannelNames": strings.Join(channelNames, ", ")})
+			bodyPage.Props["Info"] = map[string]interface{}{}
+			bodyPage.Props["Button"] = utils.T("api.templates.invite_body.button")
+			if len(message) > 0 {
+				bodyPage.Html["ExtraInfo"] = utils.TranslateAsHtml(utils.T, "api.templates.invite_guest_body.message",
+					map[string]interface{}{"SenderName": senderName, "Message": message})
+			} else {
+				bodyPage.Html["ExtraInfo"] = utils.TranslateAsHtml(utils.T, "api.templates.invite_body.extra_info",
+					map[string]interface{}{"TeamDisplayName": team.DisplayName, "TeamURL": siteURL + "/" + team.Name})
+			}
+
+			token := model.NewToken(
+				TOKEN_TYPE_GUEST_INVITATION,
+				model.MapToJson(map[string]string{"teamId": team.Id, "channels": strings.Join(channelIds, " "), "email": invite}),
+			)
+
+			props := make(map[string]string)
+			props["email"] = invite
+			props["display_name"] = team.DisplayName
+			props["name"] = team.Name
+			data := model.MapToJson(props)
+
+			if result := <-a.Srv.Store.Token().Save(token); result.Err != nil {
+				mlog.Error(fmt.Sprintf("Failed to send guest invite email successfully err=%v", result.Err))
+				continue
+			}
+			bodyPage.Props["Link"] = fmt.Sprintf("%s/signup_user_complete/?d=%s&t=%s", siteURL, url.QueryEscape(data), url.QueryEscape(token.Token))
+
+			if !a.Config().EmailSettings.SendEmailNotifications {
+				mlog.Info(fmt.Sprintf("sending guest invitation to %v %v", invite, bodyPage.Props["Link"]))
+			}
+
+			if err := a.SendMail(invite, subject, bodyPage.Render()); err != nil {
+				mlog.Error(fmt.Sprintf("Failed to send guest invite email successfully err=%v", err))
+			}
+		}
+	}
+}
+
+// createInvitationToken saves the token for an invitation. The channels that the invitee will be
+// added to are stored in the token so that they're joined automatically when it's used.
+func (a *App) createInvitationToken(team *model.Team, invite *model.Invite) (*model.Token, *model.AppError) {
+	tokenType := TOKEN_TYPE_TEAM_INVITATION
+	if invite.Role == model.INVITE_ROLE_GUEST {
+		tokenType = TOKEN_TYPE_GUEST_INVITATION
+	}
+
+	token := model.NewToken(
+		tokenType,
+		model.MapToJson(map[string]string{"teamId": team.Id, "channels": strings.Join(invite.Channels, " "), "email": invite.Email}),
+	)
+
+	if result := <-a.Srv.Store.Token().Save(token); result.Err != nil {
+		return nil, result.Err
+	}
+
+	return token, nil
+}
+
+// SendInviteEmail sends a single invitation to join a team with the role and channels that it was
+// created with.
+func (a *App) SendInviteEmail(team *model.Team, channels []*model.Channel, senderName string, invite *model.Invite, siteURL string) *model.AppError {
+	channelNames := make([]string, 0, len(channels))
+	for _, channel := range channels {
+		channelNames = append(channelNames, channel.DisplayName)
+	}
+
+	subjectId := "api.templates.invite_subject"
+	if invite.Role == model.INVITE_ROLE_GUEST {
+		subjectId = "api.templates.invite_guest_subject"
+	}
+	subject := utils.T(subjectId,
+		map[string]interface{}{"SenderName": senderName,
+			"TeamDisplayName": team.DisplayName,
+			"SiteName":        a.ClientConfig()["SiteName"]})
+
+	bodyPage := a.NewEmailTemplate("invite_body", model.DEFAULT_LOCALE)
+	bodyPage.Props["SiteURL"] = siteURL
+	bodyPage.Props["Title"] = utils.T("api.templates.invite_body.title")
+	if invite.Role == model.INVITE_ROLE_GUEST {
+		bodyPage.Html["Info"] = utils.TranslateAsHtml(utils.T, "api.templates.invite_guest_body.info",
+			map[string]interface{}{"SenderName": senderName, "TeamDisplayName": team.DisplayName, "ChannelNames": strings.Join(channelNames, ", ")})
+	} else {
+		bodyPage.Html["Info"] = utils.TranslateAsHtml(utils.T, "api.templates.invite_body.info",
+			map[string]interface{}{"SenderStatus": utils.T("api.team.invite_members.member"), "SenderName": senderName, "TeamDisplayName": team.DisplayName})
+	}
+	bodyPage.Props["Info"] = map[string]interface{}{}
+	bodyPage.Props["Button"] = utils.T("api.templates.invite_body.button")
+	if len(invite.Message) > 0 {
+		bodyPage.Html["ExtraInfo"] = utils.TranslateAsHtml(utils.T, "api.templates.invite_guest_body.message",
+			map[string]interface{}{"SenderName": senderName, "Message": invite.Message})
+	} else {
+		bodyPage.Html["ExtraInfo"] = utils.TranslateAsHtml(utils.T, "api.templates.invite_body.extra_info",
+			map[string]interface{}{"TeamDisplayName": team.DisplayName, "TeamURL": siteURL + "/" + team.Name})
+	}
+
+	token, err := a.createInvitationToken(team, invite)
+	if err != nil {
+		return err
+	}
+
+	props := make(map[string]string)
+	props["email"] = invite.Email
+	props["display_name"] = team.DisplayName
+	props["name"] = team.Name
+	data := model.MapToJson(props)
+
+	bodyPage.Props["Link"] = fmt.Sprintf("%s/signup_user_complete/?d=%s&t=%s", siteURL, url.QueryEscape(data), url.QueryEscape(token.Token))
+
+	if !a.Config().EmailSettings.SendEmailNotifications {
+		mlog.Info(fmt.Sprintf("sending invitation to %v %v", invite.Email, bodyPage.Props["Link"]))
+	}
+
+	return a.SendMail(invite.Email, subject, bodyPage.Render())
+}
+
 func (a *App) NewEmailTemplate(name, locale string) *utils.HTMLTemplate {
 	t := utils.NewHTMLTemplate(a.HTMLTemplates(), name)
 
@@ -322,6 +531,21 @@ func (a *App) NewEmailTemplate(name, locale string) *utils.HTMLTemplate {
 	return t
 }
 
+// securityChangeTime describes when a change to a user's account is being made, in their timezone, so that they can
+// tell whether it was them.
+func securityChangeTime(T i18n.TranslateFunc, location *time.Location) string {
+	t := getFormattedTime(time.Now().In(location), T)
+
+	return T("api.templates.security_change_time", map[string]interface{}{
+		"Hour":     t.Hour,
+		"Minute":   t.Minute,
+		"TimeZone": t.TimeZone,
+		"Month":    t.Month,
+		"Day":      t.Day,
+		"Year":     t.Year,
+	})
+}
+
 func (a *App) SendMail(to, subject, htmlBody string) *model.AppError {
 	license := a.License()
 	return utils.SendMailUsingConfig(to, subject, htmlBody, a.Config(), license != nil && *license.Features.Compliance)
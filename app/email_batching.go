@@ -149,6 +149,7 @@ func (job *EmailBatchingJob) checkPendingNotifications(now time.Time, handler fu
 
 			// if the user has viewed any channels in this team since the notification was queued, delete
 			// all queued notifications
+			job.app.flushChannelViewsForUser(userId)
 			mchan := job.app.Srv.Store.Channel().GetMembersForUser(inspectedTeamNames[notification.teamName], userId)
 			if result := <-mchan; result.Err != nil {
 				mlog.Error(fmt.Sprint("Unable to find ChannelMembers for user", result.Err))
@@ -205,6 +206,7 @@ func (a *App) sendBatchedEmailNotification(userId string, notifications []*batch
 	}
 
 	translateFunc := utils.GetUserTranslations(user.Locale)
+	location := a.GetUserTimezoneLocation(user)
 	displayNameFormat := *a.Config().TeamSettings.TeammateNameDisplay
 
 	var contents string
@@ -232,10 +234,10 @@ func (a *App) sendBatchedEmailNotification(userId string, notifications []*batch
 			emailNotificationContentsType = *a.Config().EmailSettings.EmailNotificationContentsType
 		}
 
-		contents += a.renderBatchedPost(notification, channel, sender, *a.Config().ServiceSettings.SiteURL, displayNameFormat, translateFunc, user.Locale, emailNotificationContentsType)
+		contents += a.renderBatchedPost(notification, channel, sender, *a.Config().ServiceSettings.SiteURL, displayNameFormat, translateFunc, user.Locale, location, emailNotificationContentsType)
 	}
 
-	tm := time.Unix(notifications[0].post.CreateAt/1000, 0)
+	tm := time.Unix(notifications[0].post.CreateAt/1000, 0).In(location)
 
 	subject := translateFunc("api.email_batching.send_batched_email_notification.subject", len(notifications), map[string]interface{}{
 		"SiteName": a.Config().TeamSettings.SiteName,
@@ -254,7 +256,7 @@ func (a *App) sendBatchedEmailNotification(userId string, notifications []*batch
 	}
 }
 
-func (a *App) renderBatchedPost(notification *batchedNotification, channel *model.Channel, sender *model.User, siteURL string, displayNameFormat string, translateFunc i18n.TranslateFunc, userLocale string, emailNotificationContentsType string) string {
+func (a *App) renderBatchedPost(notification *batchedNotification, channel *model.Channel, sender *model.User, siteURL string, displayNameFormat string, translateFunc i18n.TranslateFunc, userLocale string, location *time.Location, emailNotificationContentsType string) string {
 	// don't include message contents if email notification contents type is set to generic
 	var template *utils.HTMLTemplate
 	if emailNotificationContentsType == model.EMAIL_NOTIFICATION_CONTENTS_FULL {
@@ -265,10 +267,10 @@ func (a *App) renderBatchedPost(notification *batchedNotification, channel *mode
 
 	template.Props["Button"] = translateFunc("api.email_batching.render_batched_post.go_to_post")
 	template.Props["PostMessage"] = a.GetMessageForNotification(notification.post, translateFunc)
-	template.Props["PostLink"] = siteURL + "/" + notification.teamName + "/pl/" + notification.post.Id
+	template.Props["PostLink"] = a.getNotificationPermalink(notification.post, notification.teamName, siteURL+"/"+notification.teamName)
 	template.Props["SenderName"] = sender.GetDisplayName(displayNameFormat)
 
-	tm := time.Unix(notification.post.CreateAt/1000, 0)
+	tm := time.Unix(notification.post.CreateAt/1000, 0).In(location)
 	timezone, _ := tm.Zone()
 
 	template.Props["Date"] = translateFunc("api.email_batching.render_batched_post.date", map[string]interface{}{
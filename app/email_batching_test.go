@@ -8,8 +8,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/store"
+	"github.com/mattermost/mattermost-server/utils"
 )
 
 func TestHandleNewNotifications(t *testing.T) {
@@ -303,7 +307,7 @@ func TestRenderBatchedPostGeneric(t *testing.T) {
 		return translationID
 	}
 
-	var rendered = th.App.renderBatchedPost(notification, channel, sender, "http://localhost:8065", "", translateFunc, "en", model.EMAIL_NOTIFICATION_CONTENTS_GENERIC)
+	var rendered = th.App.renderBatchedPost(notification, channel, sender, "http://localhost:8065", "", translateFunc, "en", time.UTC, model.EMAIL_NOTIFICATION_CONTENTS_GENERIC)
 	if strings.Contains(rendered, post.Message) {
 		t.Fatal("Rendered email should not contain post contents when email notification contents type is set to Generic.")
 	}
@@ -330,8 +334,29 @@ func TestRenderBatchedPostFull(t *testing.T) {
 		return translationID
 	}
 
-	var rendered = th.App.renderBatchedPost(notification, channel, sender, "http://localhost:8065", "", translateFunc, "en", model.EMAIL_NOTIFICATION_CONTENTS_FULL)
+	var rendered = th.App.renderBatchedPost(notification, channel, sender, "http://localhost:8065", "", translateFunc, "en", time.UTC, model.EMAIL_NOTIFICATION_CONTENTS_FULL)
 	if !strings.Contains(rendered, post.Message) {
 		t.Fatal("Rendered email should contain post contents when email notification contents type is set to Full.")
 	}
 }
+
+func TestRenderBatchedPostInTimezone(t *testing.T) {
+	th := Setup()
+	defer th.TearDown()
+
+	notification := &batchedNotification{post: &model.Post{Message: "This is the message", CreateAt: 1501804801000}}
+	channel := &model.Channel{DisplayName: "Some Test Channel"}
+	sender := &model.User{Email: "sender@test.com"}
+	translateFunc := utils.GetUserTranslations("en")
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.Nil(t, err)
+	bogota, err := time.LoadLocation("America/Bogota")
+	require.Nil(t, err)
+
+	rendered := th.App.renderBatchedPost(notification, channel, sender, "http://localhost:8065", "", translateFunc, "en", tokyo, model.EMAIL_NOTIFICATION_CONTENTS_FULL)
+	assert.Contains(t, rendered, "09:00 JST, August 4")
+
+	rendered = th.App.renderBatchedPost(notification, channel, sender, "http://localhost:8065", "", translateFunc, "en", bogota, model.EMAIL_NOTIFICATION_CONTENTS_FULL)
+	assert.Contains(t, rendered, "19:00 -05, August 3")
+}
@@ -0,0 +1,316 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const (
+	TOKEN_TYPE_EMAIL_CHANGE      = "email_change"
+	TOKEN_TYPE_EMAIL_CHANGE_UNDO = "email_change_undo"
+	EMAIL_CHANGE_EXPIRY_TIME     = model.MAX_TOKEN_EXIPRY_TIME
+	EMAIL_CHANGE_UNDO_PATH       = "/undo_email_change"
+
+	// TOKEN_TYPE_EMAIL_CHANGE_PENDING tokens record which address each pending change is to, stored under a hash of
+	// the address so that a change can be found by it without reading every pending change.
+	TOKEN_TYPE_EMAIL_CHANGE_PENDING = "email_change_pending"
+
+	// TOKEN_TYPE_EMAIL_CHANGE_USER tokens hold the verification token of each user's pending change, stored under a
+	// hash of the user's id so that the change can be cancelled without reading every pending change.
+	TOKEN_TYPE_EMAIL_CHANGE_USER = "email_change_user"
+)
+
+// emailChange is stored in the extra data of the tokens used to verify and undo a change to a user's email address.
+type emailChange struct {
+	UserId   string `json:"user_id"`
+	OldEmail string `json:"old_email"`
+	NewEmail string `json:"new_email"`
+}
+
+func emailChangeFromToken(token *model.Token) *emailChange {
+	var change *emailChange
+	if err := json.Unmarshal([]byte(token.Extra), &change); err != nil || change == nil {
+		return nil
+	}
+
+	return change
+}
+
+// pendingEmailChangeToken returns the token under which a pending change to newEmail is recorded.
+func pendingEmailChangeToken(newEmail string) string {
+	hash := sha256.Sum256([]byte(TOKEN_TYPE_EMAIL_CHANGE_PENDING + ":" + strings.ToLower(newEmail)))
+	return hex.EncodeToString(hash[:])
+}
+
+// userEmailChangeToken returns the token under which the verification token of a user's pending change is recorded.
+func userEmailChangeToken(userId string) string {
+	hash := sha256.Sum256([]byte(TOKEN_TYPE_EMAIL_CHANGE_USER + ":" + userId))
+	return hex.EncodeToString(hash[:])
+}
+
+// holdEmailChange keeps a user's email address from being changed until the new address has been verified. If it
+// needs to be verified, the user's email address is reset to their current one and the new address is returned so
+// that it can be passed to requestEmailChange once the rest of the user has been updated. System admins, users
+// whose email addresses come from elsewhere, and servers that can't send email skip verification.
+func (a *App) holdEmailChange(user *model.User, asAdmin bool) (string, *model.AppError) {
+	if asAdmin || !a.Config().EmailSettings.SendEmailNotifications {
+		return "", nil
+	}
+
+	prev, err := a.GetUser(user.Id)
+	if err != nil {
+		return "", err
+	}
+
+	newEmail := strings.ToLower(user.Email)
+	if prev.AuthService != "" || newEmail == prev.Email {
+		return "", nil
+	}
+
+	if !model.IsValidEmail(newEmail) {
+		return "", model.NewAppError("holdEmailChange", "model.user.is_valid.email.app_error", nil, "user_id="+user.Id, http.StatusBadRequest)
+	}
+
+	if !CheckUserDomain(&model.User{Email: newEmail}, a.Config().TeamSettings.RestrictCreationToDomains) {
+		return "", model.NewAppError("holdEmailChange", "api.user.create_user.accepted_domain.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if existing, _ := a.GetUserByEmail(newEmail); existing != nil {
+		return "", model.NewAppError("holdEmailChange", "store.sql_user.update.email_taken.app_error", nil, "user_id="+user.Id, http.StatusBadRequest)
+	}
+
+	user.Email = prev.Email
+
+	return newEmail, nil
+}
+
+// requestEmailChange starts changing a user's email address to newEmail. A link to verify the new address is sent
+// to it, and the user's current address is told about the change with a link to undo it. Any earlier change that
+// hasn't been verified yet is cancelled.
+func (a *App) requestEmailChange(user *model.User, newEmail string) *model.AppError {
+	if err := a.cancelEmailChange(user.Id); err != nil {
+		return err
+	}
+
+	extra, _ := json.Marshal(&emailChange{UserId: user.Id, OldEmail: user.Email, NewEmail: newEmail})
+
+	verifyToken := model.NewToken(TOKEN_TYPE_EMAIL_CHANGE, string(extra))
+	if result := <-a.Srv.Store.Token().Save(verifyToken); result.Err != nil {
+		return result.Err
+	}
+
+	undoToken := model.NewToken(TOKEN_TYPE_EMAIL_CHANGE_UNDO, string(extra))
+	if result := <-a.Srv.Store.Token().Save(undoToken); result.Err != nil {
+		return result.Err
+	}
+
+	// Only the latest change to an address is recorded, even if it was made by another user
+	pendingToken := model.NewToken(TOKEN_TYPE_EMAIL_CHANGE_PENDING, string(extra))
+	pendingToken.Token = pendingEmailChangeToken(newEmail)
+	<-a.Srv.Store.Token().Delete(pendingToken.Token)
+	if result := <-a.Srv.Store.Token().Save(pendingToken); result.Err != nil {
+		return result.Err
+	}
+
+	userToken := model.NewToken(TOKEN_TYPE_EMAIL_CHANGE_USER, verifyToken.Token)
+	userToken.Token = userEmailChangeToken(user.Id)
+	if result := <-a.Srv.Store.Token().Save(userToken); result.Err != nil {
+		return result.Err
+	}
+
+	siteURL := a.GetSiteURL()
+	a.Go(func() {
+		if err := a.SendEmailChangeVerifyEmail(newEmail, user.Locale, siteURL, verifyToken.Token); err != nil {
+			mlog.Error(err.Error())
+		}
+	})
+
+	a.Go(func() {
+		if err := a.SendEmailChangePendingEmail(user.Email, newEmail, user.Locale, siteURL, undoToken.Token); err != nil {
+			mlog.Error(err.Error())
+		}
+	})
+
+	return nil
+}
+
+// getPendingEmailChange returns the change that's waiting for newEmail to be verified, or nil if there isn't one.
+func (a *App) getPendingEmailChange(newEmail string) *emailChange {
+	result := <-a.Srv.Store.Token().GetByToken(pendingEmailChangeToken(newEmail))
+	if result.Err != nil {
+		return nil
+	}
+
+	token := result.Data.(*model.Token)
+	if token.Type != TOKEN_TYPE_EMAIL_CHANGE_PENDING || model.GetMillis()-token.CreateAt >= EMAIL_CHANGE_EXPIRY_TIME {
+		return nil
+	}
+
+	return emailChangeFromToken(token)
+}
+
+// deletePendingEmailChange removes the record of a user's change to newEmail, leaving any later change to the same
+// address by another user.
+func (a *App) deletePendingEmailChange(userId string, newEmail string) *model.AppError {
+	if change := a.getPendingEmailChange(newEmail); change == nil || change.UserId != userId {
+		return nil
+	}
+
+	if result := <-a.Srv.Store.Token().Delete(pendingEmailChangeToken(newEmail)); result.Err != nil {
+		return result.Err
+	}
+
+	return nil
+}
+
+// checkPendingEmailChangeForLogin returns an error explaining that a user has to sign in with their current email
+// address if they try to sign in with one that they're still waiting to change to.
+func (a *App) checkPendingEmailChangeForLogin(loginId string) *model.AppError {
+	if !strings.Contains(loginId, "@") {
+		return nil
+	}
+
+	if change := a.getPendingEmailChange(loginId); change != nil {
+		return model.NewAppError("GetUserForLogin", "api.user.login.email_change_pending.app_error", nil, "user_id="+change.UserId, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+// confirmEmailChange changes a user's email address after they've verified the new one.
+func (a *App) confirmEmailChange(token *model.Token) *model.AppError {
+	if model.GetMillis()-token.CreateAt >= EMAIL_CHANGE_EXPIRY_TIME {
+		return model.NewAppError("confirmEmailChange", "api.user.email_change.expired.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	change := emailChangeFromToken(token)
+	if change == nil {
+		return model.NewAppError("confirmEmailChange", "api.user.verify_email.broken_token.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	user, err := a.GetUser(change.UserId)
+	if err != nil {
+		return err
+	}
+
+	// The change was made from an address that the user no longer has, so it shouldn't replace their current one
+	if user.Email != change.OldEmail {
+		return model.NewAppError("confirmEmailChange", "api.user.email_change.expired.app_error", nil, "user_id="+user.Id, http.StatusBadRequest)
+	}
+
+	user.Email = change.NewEmail
+	updatedUser, err := a.UpdateUser(user, false)
+	if err != nil {
+		return err
+	}
+
+	if err := a.VerifyUserEmail(user.Id); err != nil {
+		return err
+	}
+	updatedUser.EmailVerified = true
+
+	if err := a.DeleteToken(token); err != nil {
+		mlog.Error(err.Error())
+	}
+	if err := a.deletePendingEmailChange(change.UserId, change.NewEmail); err != nil {
+		mlog.Error(err.Error())
+	}
+	if result := <-a.Srv.Store.Token().Delete(userEmailChangeToken(change.UserId)); result.Err != nil {
+		mlog.Error(result.Err.Error())
+	}
+
+	a.sendUpdatedUserEvent(*updatedUser)
+
+	return nil
+}
+
+// UndoEmailChange cancels a change to a user's email address from the link sent to their old address, or changes
+// it back if the new address has already been verified. The user is signed out everywhere in case someone else
+// made the change.
+func (a *App) UndoEmailChange(tokenString string) *model.AppError {
+	result := <-a.Srv.Store.Token().GetByToken(tokenString)
+	if result.Err != nil {
+		return model.NewAppError("UndoEmailChange", "api.user.email_change.undo.bad_link.app_error", nil, result.Err.Error(), http.StatusBadRequest)
+	}
+
+	token := result.Data.(*model.Token)
+	if token.Type != TOKEN_TYPE_EMAIL_CHANGE_UNDO {
+		return model.NewAppError("UndoEmailChange", "api.user.email_change.undo.bad_link.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if model.GetMillis()-token.CreateAt >= EMAIL_CHANGE_EXPIRY_TIME {
+		return model.NewAppError("UndoEmailChange", "api.user.email_change.expired.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	change := emailChangeFromToken(token)
+	if change == nil {
+		return model.NewAppError("UndoEmailChange", "api.user.email_change.undo.bad_link.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if err := a.cancelEmailChange(change.UserId); err != nil {
+		return err
+	}
+
+	user, err := a.GetUser(change.UserId)
+	if err != nil {
+		return err
+	}
+
+	if user.Email == change.NewEmail {
+		user.Email = change.OldEmail
+		updatedUser, err := a.UpdateUser(user, false)
+		if err != nil {
+			return err
+		}
+
+		if err := a.VerifyUserEmail(user.Id); err != nil {
+			return err
+		}
+		updatedUser.EmailVerified = true
+
+		a.sendUpdatedUserEvent(*updatedUser)
+	}
+
+	if err := a.DeleteToken(token); err != nil {
+		return err
+	}
+
+	return a.RevokeAllSessions(change.UserId)
+}
+
+// cancelEmailChange cancels a user's change to their email address that hasn't been verified yet, if they have one.
+func (a *App) cancelEmailChange(userId string) *model.AppError {
+	result := <-a.Srv.Store.Token().GetByToken(userEmailChangeToken(userId))
+	if result.Err != nil {
+		if result.Err.StatusCode == http.StatusBadRequest {
+			return nil
+		}
+		return result.Err
+	}
+	userToken := result.Data.(*model.Token)
+
+	if result := <-a.Srv.Store.Token().GetByToken(userToken.Extra); result.Err == nil {
+		verifyToken := result.Data.(*model.Token)
+
+		if change := emailChangeFromToken(verifyToken); change != nil {
+			if err := a.deletePendingEmailChange(userId, change.NewEmail); err != nil {
+				return err
+			}
+		}
+
+		if err := a.DeleteToken(verifyToken); err != nil {
+			return err
+		}
+	}
+
+	return a.DeleteToken(userToken)
+}
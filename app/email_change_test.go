@@ -0,0 +1,196 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestEmailChange(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { cfg.EmailSettings.SendEmailNotifications = true })
+
+	getToken := func(t *testing.T, tokenType string, userId string) *model.Token {
+		result := <-th.App.Srv.Store.Token().GetAllTokensByType(tokenType)
+		require.Nil(t, result.Err)
+		for _, token := range result.Data.([]*model.Token) {
+			if change := emailChangeFromToken(token); change != nil && change.UserId == userId {
+				return token
+			}
+		}
+		return nil
+	}
+
+	changeEmail := func(t *testing.T, user *model.User) string {
+		newEmail := "success+" + model.NewId() + "@simulator.amazonses.com"
+		updated, err := th.App.PatchUser(user.Id, &model.UserPatch{Email: model.NewString(newEmail)}, false)
+		require.Nil(t, err)
+		assert.Equal(t, user.Email, updated.Email, "the email shouldn't change until it's verified")
+		return newEmail
+	}
+
+	t.Run("pending until verified", func(t *testing.T) {
+		user := th.CreateUser()
+		newEmail := changeEmail(t, user)
+
+		pending := th.App.getPendingEmailChange(strings.ToUpper(newEmail))
+		require.NotNil(t, pending)
+		assert.Equal(t, user.Id, pending.UserId)
+
+		_, err := th.App.GetUserForLogin("", newEmail)
+		require.NotNil(t, err, "the pending address can't be used to sign in")
+		assert.Equal(t, "api.user.login.email_change_pending.app_error", err.Id)
+
+		_, err = th.App.GetUserForLogin("", user.Email)
+		require.Nil(t, err, "the current address can still be used to sign in")
+
+		token := getToken(t, TOKEN_TYPE_EMAIL_CHANGE, user.Id)
+		require.NotNil(t, token)
+		require.Nil(t, th.App.VerifyEmailFromToken(token.Token))
+
+		updated, err := th.App.GetUser(user.Id)
+		require.Nil(t, err)
+		assert.Equal(t, newEmail, updated.Email)
+		assert.True(t, updated.EmailVerified)
+
+		assert.Nil(t, th.App.getPendingEmailChange(newEmail))
+
+		require.NotNil(t, th.App.VerifyEmailFromToken(token.Token), "the token can only be used once")
+	})
+
+	t.Run("a new change replaces the pending one", func(t *testing.T) {
+		user := th.CreateUser()
+		oldEmail := changeEmail(t, user)
+		oldToken := getToken(t, TOKEN_TYPE_EMAIL_CHANGE, user.Id)
+		require.NotNil(t, oldToken)
+		newEmail := changeEmail(t, user)
+
+		assert.Nil(t, th.App.getPendingEmailChange(oldEmail))
+		assert.NotNil(t, th.App.getPendingEmailChange(newEmail))
+		require.NotNil(t, th.App.VerifyEmailFromToken(oldToken.Token), "the earlier change should be cancelled")
+
+		result := <-th.App.Srv.Store.Token().GetByToken(userEmailChangeToken(user.Id))
+		require.Nil(t, result.Err)
+		assert.NotEqual(t, oldToken.Token, result.Data.(*model.Token).Extra)
+	})
+
+	t.Run("a change to the same address by another user replaces the pending one", func(t *testing.T) {
+		user := th.CreateUser()
+		newEmail := changeEmail(t, user)
+
+		user2 := th.CreateUser()
+		_, err := th.App.PatchUser(user2.Id, &model.UserPatch{Email: model.NewString(newEmail)}, false)
+		require.Nil(t, err)
+
+		pending := th.App.getPendingEmailChange(newEmail)
+		require.NotNil(t, pending)
+		assert.Equal(t, user2.Id, pending.UserId)
+
+		// Cancelling the first user's change leaves the second one's
+		require.Nil(t, th.App.UndoEmailChange(getToken(t, TOKEN_TYPE_EMAIL_CHANGE_UNDO, user.Id).Token))
+		assert.NotNil(t, th.App.getPendingEmailChange(newEmail))
+	})
+
+	t.Run("undo before verifying", func(t *testing.T) {
+		user := th.CreateUser()
+		newEmail := changeEmail(t, user)
+
+		session, err := th.App.CreateSession(&model.Session{UserId: user.Id})
+		require.Nil(t, err)
+
+		undoToken := getToken(t, TOKEN_TYPE_EMAIL_CHANGE_UNDO, user.Id)
+		require.NotNil(t, undoToken)
+		verifyToken := getToken(t, TOKEN_TYPE_EMAIL_CHANGE, user.Id)
+		require.NotNil(t, verifyToken)
+
+		require.Nil(t, th.App.UndoEmailChange(undoToken.Token))
+
+		assert.Nil(t, th.App.getPendingEmailChange(newEmail))
+
+		require.NotNil(t, th.App.VerifyEmailFromToken(verifyToken.Token), "the change should be cancelled")
+		_, err = th.App.GetSession(session.Token)
+		assert.NotNil(t, err, "the user should be signed out")
+
+		updated, err := th.App.GetUser(user.Id)
+		require.Nil(t, err)
+		assert.Equal(t, user.Email, updated.Email)
+		assert.NotEqual(t, newEmail, updated.Email)
+	})
+
+	t.Run("undo after verifying", func(t *testing.T) {
+		user := th.CreateUser()
+		changeEmail(t, user)
+
+		undoToken := getToken(t, TOKEN_TYPE_EMAIL_CHANGE_UNDO, user.Id)
+		require.NotNil(t, undoToken)
+		require.Nil(t, th.App.VerifyEmailFromToken(getToken(t, TOKEN_TYPE_EMAIL_CHANGE, user.Id).Token))
+
+		require.Nil(t, th.App.UndoEmailChange(undoToken.Token))
+
+		updated, err := th.App.GetUser(user.Id)
+		require.Nil(t, err)
+		assert.Equal(t, user.Email, updated.Email, "the old address should be restored")
+		assert.True(t, updated.EmailVerified)
+
+		require.NotNil(t, th.App.UndoEmailChange(undoToken.Token), "the token can only be used once")
+	})
+
+	t.Run("expired tokens", func(t *testing.T) {
+		user := th.CreateUser()
+		newEmail := "success+" + model.NewId() + "@simulator.amazonses.com"
+		extra, _ := json.Marshal(&emailChange{UserId: user.Id, OldEmail: user.Email, NewEmail: newEmail})
+
+		verifyToken := model.NewToken(TOKEN_TYPE_EMAIL_CHANGE, string(extra))
+		verifyToken.CreateAt = model.GetMillis() - EMAIL_CHANGE_EXPIRY_TIME - 1
+		require.Nil(t, (<-th.App.Srv.Store.Token().Save(verifyToken)).Err)
+
+		undoToken := model.NewToken(TOKEN_TYPE_EMAIL_CHANGE_UNDO, string(extra))
+		undoToken.CreateAt = verifyToken.CreateAt
+		require.Nil(t, (<-th.App.Srv.Store.Token().Save(undoToken)).Err)
+
+		pendingToken := model.NewToken(TOKEN_TYPE_EMAIL_CHANGE_PENDING, string(extra))
+		pendingToken.Token = pendingEmailChangeToken(newEmail)
+		pendingToken.CreateAt = verifyToken.CreateAt
+		require.Nil(t, (<-th.App.Srv.Store.Token().Save(pendingToken)).Err)
+
+		assert.Nil(t, th.App.getPendingEmailChange(newEmail), "expired changes aren't pending")
+
+		err := th.App.VerifyEmailFromToken(verifyToken.Token)
+		require.NotNil(t, err)
+		assert.Equal(t, "api.user.email_change.expired.app_error", err.Id)
+
+		err = th.App.UndoEmailChange(undoToken.Token)
+		require.NotNil(t, err)
+		assert.Equal(t, "api.user.email_change.expired.app_error", err.Id)
+
+		updated, err := th.App.GetUser(user.Id)
+		require.Nil(t, err)
+		assert.Equal(t, user.Email, updated.Email)
+	})
+
+	t.Run("taken address", func(t *testing.T) {
+		user := th.CreateUser()
+		_, err := th.App.PatchUser(user.Id, &model.UserPatch{Email: model.NewString(strings.ToUpper(th.BasicUser2.Email))}, false)
+		require.NotNil(t, err)
+		assert.Equal(t, "store.sql_user.update.email_taken.app_error", err.Id)
+	})
+
+	t.Run("admins skip verification", func(t *testing.T) {
+		user := th.CreateUser()
+		newEmail := "success+" + model.NewId() + "@simulator.amazonses.com"
+		updated, err := th.App.PatchUser(user.Id, &model.UserPatch{Email: model.NewString(newEmail)}, true)
+		require.Nil(t, err)
+		assert.Equal(t, newEmail, updated.Email)
+		assert.Nil(t, getToken(t, TOKEN_TYPE_EMAIL_CHANGE, user.Id))
+	})
+}
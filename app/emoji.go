@@ -64,6 +64,58 @@ func (a *App) CreateEmoji(sessionUserId string, emoji *model.Emoji, multiPartIma
 	}
 }
 
+// ImportEmoji creates a custom emoji from image data without a user's session, such as when importing emoji
+// from another server. The image is checked the same way as it is by CreateEmoji. If an emoji with the same
+// name already exists, it's replaced when overwrite is set, keeping the reactions that use it.
+func (a *App) ImportEmoji(creatorId string, name string, filename string, data []byte, overwrite bool) (*model.Emoji, *model.AppError) {
+	if !*a.Config().ServiceSettings.EnableCustomEmoji {
+		return nil, model.NewAppError("ImportEmoji", "api.emoji.disabled.app_error", nil, "", http.StatusNotImplemented)
+	}
+
+	if len(*a.Config().FileSettings.DriverName) == 0 {
+		return nil, model.NewAppError("ImportEmoji", "api.emoji.storage.app_error", nil, "", http.StatusNotImplemented)
+	}
+
+	if len(data) > MaxEmojiFileSize {
+		return nil, model.NewAppError("ImportEmoji", "api.emoji.create.too_large.app_error", nil, "", http.StatusRequestEntityTooLarge)
+	}
+
+	emoji := &model.Emoji{CreatorId: creatorId, Name: name}
+	emoji.PreSave()
+	if err := emoji.IsValid(); err != nil {
+		return nil, err
+	}
+
+	var existing *model.Emoji
+	if result := <-a.Srv.Store.Emoji().GetByName(emoji.Name); result.Err == nil && result.Data != nil {
+		if !overwrite {
+			return nil, model.NewAppError("ImportEmoji", "api.emoji.create.duplicate.app_error", nil, "", http.StatusBadRequest)
+		}
+		existing = result.Data.(*model.Emoji)
+	}
+
+	if err := a.uploadEmojiImageData(emoji.Id, filename, bytes.NewBuffer(data)); err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		if result := <-a.Srv.Store.Emoji().Delete(existing.Id, model.GetMillis()); result.Err != nil {
+			return nil, result.Err
+		}
+		a.deleteEmojiImage(existing.Id)
+	}
+
+	if result := <-a.Srv.Store.Emoji().Save(emoji); result.Err != nil {
+		return nil, result.Err
+	}
+
+	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_EMOJI_ADDED, "", "", "", nil)
+	message.Add("emoji", emoji.ToJson())
+	a.Publish(message)
+
+	return emoji, nil
+}
+
 func (a *App) GetEmojiList(page, perPage int, sort string) ([]*model.Emoji, *model.AppError) {
 	if result := <-a.Srv.Store.Emoji().GetList(page*perPage, perPage, sort); result.Err != nil {
 		return nil, result.Err
@@ -72,6 +124,57 @@ func (a *App) GetEmojiList(page, perPage int, sort string) ([]*model.Emoji, *mod
 	}
 }
 
+// RollUpEmojiStats recounts how much each custom emoji has been used.
+func (a *App) RollUpEmojiStats() *model.AppError {
+	if result := <-a.Srv.Store.Emoji().RollUpStats(model.GetMillis()); result.Err != nil {
+		return result.Err
+	}
+
+	return nil
+}
+
+func (a *App) GetEmojiStats(page, perPage int) ([]*model.EmojiStats, *model.AppError) {
+	if !*a.Config().ServiceSettings.EnableCustomEmoji {
+		return nil, model.NewAppError("GetEmojiStats", "api.emoji.disabled.app_error", nil, "", http.StatusNotImplemented)
+	}
+
+	if result := <-a.Srv.Store.Emoji().GetStats(page*perPage, perPage); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return result.Data.([]*model.EmojiStats), nil
+	}
+}
+
+// DeleteUnusedEmoji deletes the custom emoji which are more than the given number of days old and which haven't
+// been used as a reaction in that time, and returns them.
+func (a *App) DeleteUnusedEmoji(days int) ([]*model.Emoji, *model.AppError) {
+	if !*a.Config().ServiceSettings.EnableCustomEmoji {
+		return nil, model.NewAppError("DeleteUnusedEmoji", "api.emoji.disabled.app_error", nil, "", http.StatusNotImplemented)
+	}
+
+	if days < 1 {
+		return nil, model.NewAppError("DeleteUnusedEmoji", "app.emoji.delete_unused.days.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	before := model.GetMillis() - int64(days)*24*60*60*1000
+
+	result := <-a.Srv.Store.Emoji().GetUnused(before)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	deleted := []*model.Emoji{}
+	for _, emoji := range result.Data.([]*model.Emoji) {
+		if err := a.DeleteEmoji(emoji); err != nil {
+			mlog.Warn(fmt.Sprintf("Unable to delete unused emoji %v, err=%v", emoji.Name, err))
+			continue
+		}
+		deleted = append(deleted, emoji)
+	}
+
+	return deleted, nil
+}
+
 func (a *App) UploadEmojiImage(id string, imageData *multipart.FileHeader) *model.AppError {
 	file, err := imageData.Open()
 	if err != nil {
@@ -82,13 +185,17 @@ func (a *App) UploadEmojiImage(id string, imageData *multipart.FileHeader) *mode
 	buf := bytes.NewBuffer(nil)
 	io.Copy(buf, file)
 
+	return a.uploadEmojiImageData(id, imageData.Filename, buf)
+}
+
+func (a *App) uploadEmojiImageData(id string, filename string, buf *bytes.Buffer) *model.AppError {
 	// make sure the file is an image and is within the required dimensions
 	if config, _, err := image.DecodeConfig(bytes.NewReader(buf.Bytes())); err != nil {
 		return model.NewAppError("uploadEmojiImage", "api.emoji.upload.image.app_error", nil, "", http.StatusBadRequest)
 	} else if config.Width > MaxEmojiWidth || config.Height > MaxEmojiHeight {
 		data := buf.Bytes()
 		newbuf := bytes.NewBuffer(nil)
-		if info, err := model.GetInfoForBytes(imageData.Filename, data); err != nil {
+		if info, err := model.GetInfoForBytes(filename, data); err != nil {
 			return err
 		} else if info.MimeType == "image/gif" {
 			if gif_data, err := gif.DecodeAll(bytes.NewReader(data)); err != nil {
@@ -128,6 +235,7 @@ func (a *App) DeleteEmoji(emoji *model.Emoji) *model.AppError {
 
 	a.deleteEmojiImage(emoji.Id)
 	a.deleteReactionsForEmoji(emoji.Name)
+	a.clearChannelIconsForEmoji(emoji.Name)
 	return nil
 }
 
@@ -0,0 +1,127 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+func TestImportEmoji(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableCustomEmoji = true })
+
+	name := "import" + model.NewId()
+
+	emoji, err := th.App.ImportEmoji(th.BasicUser.Id, name, "image.png", utils.CreateTestPng(t, 10, 10), false)
+	require.Nil(t, err)
+	assert.Equal(t, name, emoji.Name)
+	assert.Equal(t, th.BasicUser.Id, emoji.CreatorId)
+
+	_, err = th.App.SaveReactionForPost(&model.Reaction{UserId: th.BasicUser.Id, PostId: th.BasicPost.Id, EmojiName: name})
+	require.Nil(t, err)
+
+	t.Run("duplicate", func(t *testing.T) {
+		_, err := th.App.ImportEmoji(th.BasicUser.Id, name, "image.gif", utils.CreateTestGif(t, 10, 10), false)
+		if assert.NotNil(t, err) {
+			assert.Equal(t, "api.emoji.create.duplicate.app_error", err.Id)
+		}
+
+		existing, err := th.App.GetEmojiByName(name)
+		require.Nil(t, err)
+		assert.Equal(t, emoji.Id, existing.Id)
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		replacement, err := th.App.ImportEmoji(th.BasicUser2.Id, name, "image.gif", utils.CreateTestGif(t, 10, 10), true)
+		require.Nil(t, err)
+		assert.NotEqual(t, emoji.Id, replacement.Id)
+
+		existing, err := th.App.GetEmojiByName(name)
+		require.Nil(t, err)
+		assert.Equal(t, replacement.Id, existing.Id)
+
+		_, err = th.App.GetEmoji(emoji.Id)
+		assert.NotNil(t, err)
+
+		// reactions using the name are kept
+		reactions, err := th.App.GetReactionsForPost(th.BasicPost.Id)
+		require.Nil(t, err)
+		assert.Len(t, reactions, 1)
+	})
+
+	t.Run("invalid image", func(t *testing.T) {
+		_, err := th.App.ImportEmoji(th.BasicUser.Id, "import"+model.NewId(), "image.png", []byte("not an image"), false)
+		if assert.NotNil(t, err) {
+			assert.Equal(t, "api.emoji.upload.image.app_error", err.Id)
+		}
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		_, err := th.App.ImportEmoji(th.BasicUser.Id, "import"+model.NewId(), "image.png", make([]byte, MaxEmojiFileSize+1), false)
+		if assert.NotNil(t, err) {
+			assert.Equal(t, "api.emoji.create.too_large.app_error", err.Id)
+		}
+	})
+
+	t.Run("invalid name", func(t *testing.T) {
+		_, err := th.App.ImportEmoji(th.BasicUser.Id, "not valid", "image.png", utils.CreateTestPng(t, 10, 10), false)
+		if assert.NotNil(t, err) {
+			assert.Equal(t, "model.emoji.name.app_error", err.Id)
+		}
+	})
+}
+
+func TestEmojiStats(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableCustomEmoji = true })
+
+	emoji, err := th.App.ImportEmoji(th.BasicUser.Id, "stats"+model.NewId(), "image.png", utils.CreateTestPng(t, 10, 10), false)
+	require.Nil(t, err)
+
+	for _, post := range []*model.Post{th.BasicPost, th.CreatePost(th.BasicChannel)} {
+		_, err = th.App.SaveReactionForPost(&model.Reaction{UserId: th.BasicUser.Id, PostId: post.Id, EmojiName: emoji.Name})
+		require.Nil(t, err)
+	}
+
+	require.Nil(t, th.App.RollUpEmojiStats())
+
+	stats, err := th.App.GetEmojiStats(0, 10000)
+	require.Nil(t, err)
+
+	var found *model.EmojiStats
+	for _, s := range stats {
+		if s.EmojiId == emoji.Id {
+			found = s
+		}
+	}
+	require.NotNil(t, found)
+	assert.Equal(t, int64(2), found.UsageCount)
+	assert.NotEqual(t, int64(0), found.LastUsedAt)
+	assert.NotEqual(t, int64(0), found.UpdateAt)
+
+	t.Run("unused emoji that were just created aren't deleted", func(t *testing.T) {
+		unused, err := th.App.ImportEmoji(th.BasicUser.Id, "unused"+model.NewId(), "image.png", utils.CreateTestPng(t, 10, 10), false)
+		require.Nil(t, err)
+
+		deleted, err := th.App.DeleteUnusedEmoji(1)
+		require.Nil(t, err)
+		for _, d := range deleted {
+			assert.NotEqual(t, unused.Id, d.Id)
+			assert.NotEqual(t, emoji.Id, d.Id)
+		}
+
+		_, err = th.App.DeleteUnusedEmoji(0)
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"strings"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// GetFeatureFlagOverrides returns the feature flags that have been set through the API instead of the config.
+func (a *App) GetFeatureFlagOverrides() map[string]string {
+	a.featureFlagsLock.RLock()
+	defer a.featureFlagsLock.RUnlock()
+
+	overrides := make(map[string]string, len(a.featureFlagOverrides))
+	for name, value := range a.featureFlagOverrides {
+		overrides[name] = value
+	}
+
+	return overrides
+}
+
+// SetFeatureFlagOverrides replaces the feature flags that override the config on every server in the cluster. Flags
+// that aren't overridden go back to the value in the config.
+func (a *App) SetFeatureFlagOverrides(overrides map[string]string) *model.AppError {
+	flags := &model.FeatureFlags{}
+	for name, value := range overrides {
+		if err := flags.Set(name, value); err != nil {
+			return err
+		}
+	}
+
+	if len(overrides) == 0 {
+		if result := <-a.Srv.Store.System().PermanentDeleteByName(model.SYSTEM_FEATURE_FLAG_OVERRIDES); result.Err != nil {
+			return result.Err
+		}
+	} else {
+		if result := <-a.Srv.Store.System().SaveOrUpdate(&model.System{Name: model.SYSTEM_FEATURE_FLAG_OVERRIDES, Value: model.MapToJson(overrides)}); result.Err != nil {
+			return result.Err
+		}
+	}
+
+	a.updateFeatureFlagOverrides(overrides, true)
+
+	return nil
+}
+
+func (a *App) loadFeatureFlagOverrides() {
+	result := <-a.Srv.Store.System().Get()
+	if result.Err != nil {
+		mlog.Error("Unable to load the feature flag overrides", mlog.String("error", result.Err.Error()))
+		return
+	}
+
+	value, ok := result.Data.(model.StringMap)[model.SYSTEM_FEATURE_FLAG_OVERRIDES]
+	if !ok {
+		return
+	}
+
+	a.updateFeatureFlagOverrides(model.MapFromJson(strings.NewReader(value)), false)
+}
+
+// updateFeatureFlagOverrides applies the overrides to the config. The config listeners tell this server's clients about
+// the new values, and other servers apply them and tell their own clients if the change is sent to the cluster.
+func (a *App) updateFeatureFlagOverrides(overrides map[string]string, sendToCluster bool) {
+	a.featureFlagsLock.Lock()
+	a.featureFlagOverrides = overrides
+	a.featureFlagsLock.Unlock()
+
+	a.UpdateConfig(func(*model.Config) {})
+
+	if sendToCluster && a.Cluster != nil {
+		a.Cluster.SendClusterMessage(&model.ClusterMessage{
+			Event:    model.CLUSTER_EVENT_UPDATE_FEATURE_FLAGS,
+			SendType: model.CLUSTER_SEND_RELIABLE,
+			Data:     model.MapToJson(overrides),
+		})
+	}
+}
+
+// applyFeatureFlagOverrides sets the feature flags in the updated config to the overridden values. The values that the
+// flags had before they were overridden are kept so that they can be restored and saved to the config file. Flags
+// that changed since the old config are considered to have been configured, and all of them are if there's no old
+// config.
+func (a *App) applyFeatureFlagOverrides(old *model.Config, updated *model.Config) {
+	a.featureFlagsLock.Lock()
+	defer a.featureFlagsLock.Unlock()
+
+	updated.FeatureFlags.SetDefaults()
+
+	if old == nil || a.configuredFeatureFlags == nil {
+		a.configuredFeatureFlags = updated.FeatureFlags.ToMap()
+	} else {
+		oldFlags := old.FeatureFlags.ToMap()
+		for name, value := range updated.FeatureFlags.ToMap() {
+			if value != oldFlags[name] {
+				a.configuredFeatureFlags[name] = value
+			}
+		}
+	}
+
+	for name, value := range a.configuredFeatureFlags {
+		updated.FeatureFlags.Set(name, value)
+	}
+
+	for name, value := range a.featureFlagOverrides {
+		if err := updated.FeatureFlags.Set(name, value); err != nil {
+			mlog.Warn("Ignoring a feature flag override", mlog.String("name", name), mlog.String("error", err.Error()))
+		}
+	}
+}
+
+// configWithoutFeatureFlagOverrides returns a copy of the config that has the configured values of the feature flags
+// instead of the overridden ones.
+func (a *App) configWithoutFeatureFlagOverrides() *model.Config {
+	cfg := a.Config().Clone()
+
+	a.featureFlagsLock.RLock()
+	defer a.featureFlagsLock.RUnlock()
+
+	for name, value := range a.configuredFeatureFlags {
+		cfg.FeatureFlags.Set(name, value)
+	}
+
+	return cfg
+}
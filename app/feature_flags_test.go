@@ -0,0 +1,61 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestSetFeatureFlagOverrides(t *testing.T) {
+	th := Setup()
+	defer th.TearDown()
+
+	defer th.App.SetFeatureFlagOverrides(nil)
+
+	assert.False(t, th.App.Config().FeatureFlags.Bool("TestBoolFeature"))
+	assert.Equal(t, "false", th.App.ClientConfig()["FeatureFlagTestBoolFeature"])
+
+	require.Nil(t, th.App.SetFeatureFlagOverrides(map[string]string{"TestBoolFeature": "true"}))
+	assert.True(t, th.App.Config().FeatureFlags.Bool("TestBoolFeature"))
+	assert.Equal(t, "true", th.App.ClientConfig()["FeatureFlagTestBoolFeature"])
+	assert.False(t, *th.App.configWithoutFeatureFlagOverrides().FeatureFlags.TestBoolFeature, "overrides shouldn't be saved to the config file")
+
+	t.Run("config changes keep the overrides", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) {
+			*cfg.FeatureFlags.TestFeature = "on"
+		})
+
+		assert.True(t, th.App.Config().FeatureFlags.Bool("TestBoolFeature"))
+		assert.Equal(t, "on", th.App.ClientConfig()["FeatureFlagTestFeature"])
+	})
+
+	t.Run("overrides are loaded at startup", func(t *testing.T) {
+		th.App.updateFeatureFlagOverrides(nil, false)
+		assert.False(t, th.App.Config().FeatureFlags.Bool("TestBoolFeature"))
+
+		th.App.loadFeatureFlagOverrides()
+		assert.Equal(t, map[string]string{"TestBoolFeature": "true"}, th.App.GetFeatureFlagOverrides())
+		assert.True(t, th.App.Config().FeatureFlags.Bool("TestBoolFeature"))
+	})
+
+	err := th.App.SetFeatureFlagOverrides(map[string]string{"TestBoolFeature": "maybe"})
+	require.NotNil(t, err)
+	assert.Equal(t, "model.feature_flags.set.bool.app_error", err.Id)
+
+	err = th.App.SetFeatureFlagOverrides(map[string]string{"NotAFeature": "true"})
+	require.NotNil(t, err)
+	assert.Equal(t, "model.feature_flags.set.name.app_error", err.Id)
+	assert.True(t, th.App.Config().FeatureFlags.Bool("TestBoolFeature"), "invalid overrides shouldn't change anything")
+
+	require.Nil(t, th.App.SetFeatureFlagOverrides(nil))
+	assert.Empty(t, th.App.GetFeatureFlagOverrides())
+	assert.False(t, th.App.Config().FeatureFlags.Bool("TestBoolFeature"))
+	assert.Equal(t, "false", th.App.ClientConfig()["FeatureFlagTestBoolFeature"])
+	assert.Equal(t, "on", th.App.Config().FeatureFlags.String("TestFeature"), "configured flags should be kept")
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/mattermost/mattermost-server/mlog"
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/utils"
+	"github.com/mattermost/mattermost-server/utils/tracing"
 )
 
 const (
@@ -59,7 +60,16 @@ const (
 
 func (a *App) FileBackend() (utils.FileBackend, *model.AppError) {
 	license := a.License()
-	return utils.NewFileBackend(&a.Config().FileSettings, license != nil && *license.Features.Compliance)
+	backend, err := utils.NewFileBackend(&a.Config().FileSettings, license != nil && *license.Features.Compliance)
+	if err != nil {
+		return nil, err
+	}
+
+	if tracing.Active() {
+		return &utils.TracingFileBackend{FileBackend: backend}, nil
+	}
+
+	return backend, nil
 }
 
 func (a *App) ReadFile(path string) ([]byte, *model.AppError) {
@@ -70,6 +80,14 @@ func (a *App) ReadFile(path string) ([]byte, *model.AppError) {
 	return backend.ReadFile(path)
 }
 
+func (a *App) FileExists(path string) (bool, *model.AppError) {
+	backend, err := a.FileBackend()
+	if err != nil {
+		return false, err
+	}
+	return backend.FileExists(path)
+}
+
 func (a *App) MoveFile(oldPath, newPath string) *model.AppError {
 	backend, err := a.FileBackend()
 	if err != nil {
@@ -95,6 +113,14 @@ func (a *App) RemoveFile(path string) *model.AppError {
 	return backend.RemoveFile(path)
 }
 
+func (a *App) RemoveDirectory(path string) *model.AppError {
+	backend, err := a.FileBackend()
+	if err != nil {
+		return err
+	}
+	return backend.RemoveDirectory(path)
+}
+
 func (a *App) GetInfoForFilename(post *model.Post, teamId string, filename string) *model.FileInfo {
 	// Find the path from the Filename of the form /{channelId}/{userId}/{uid}/{nameWithExtension}
 	split := strings.SplitN(filename, "/", 5)
@@ -147,6 +173,7 @@ func (a *App) GetInfoForFilename(post *model.Post, teamId string, filename strin
 	info.Id = model.NewId()
 	info.CreatorId = post.UserId
 	info.PostId = post.Id
+	info.ChannelId = post.ChannelId
 	info.CreateAt = post.CreateAt
 	info.UpdateAt = post.UpdateAt
 	info.Path = path
@@ -335,6 +362,12 @@ func (a *App) UploadFiles(teamId string, channelId string, userId string, files
 		return nil, model.NewAppError("UploadFiles", "api.file.upload_file.incorrect_number_of_files.app_error", nil, "", http.StatusBadRequest)
 	}
 
+	// The team that the files count towards, since the one given is only used for their paths
+	channel, err := a.GetChannel(channelId)
+	if err != nil {
+		return nil, err
+	}
+
 	resStruct := &model.FileUploadResponse{
 		FileInfos: []*model.FileInfo{},
 		ClientIds: []string{},
@@ -349,12 +382,18 @@ func (a *App) UploadFiles(teamId string, channelId string, userId string, files
 		io.Copy(buf, file)
 		data := buf.Bytes()
 
-		info, err := a.DoUploadFile(time.Now(), teamId, channelId, userId, filenames[i], data)
+		if err := a.ReserveFileStorage(userId, channel.TeamId, filenames[i], int64(len(data))); err != nil {
+			return nil, err
+		}
+
+		info, duplicate, err := a.doUploadFile(time.Now(), teamId, channelId, userId, filenames[i], data)
 		if err != nil {
+			a.ReleaseFileStorage(userId, channel.TeamId, int64(len(data)))
 			return nil, err
 		}
 
-		if info.PreviewPath != "" || info.ThumbnailPath != "" {
+		// Duplicates share the preview and thumbnail that were made for the stored file
+		if !duplicate && (info.PreviewPath != "" || info.ThumbnailPath != "") {
 			previewPathList = append(previewPathList, info.PreviewPath)
 			thumbnailPathList = append(thumbnailPathList, info.ThumbnailPath)
 			imageDataList = append(imageDataList, data)
@@ -373,6 +412,14 @@ func (a *App) UploadFiles(teamId string, channelId string, userId string, files
 }
 
 func (a *App) DoUploadFile(now time.Time, rawTeamId string, rawChannelId string, rawUserId string, rawFilename string, data []byte) (*model.FileInfo, *model.AppError) {
+	info, _, err := a.doUploadFile(now, rawTeamId, rawChannelId, rawUserId, rawFilename, data)
+	return info, err
+}
+
+// doUploadFile saves the info for an uploaded file, returning whether it's a duplicate of a file that's already in the
+// file store with any preview and thumbnail that it needs. Duplicates share the paths of the stored file instead of
+// writing it again.
+func (a *App) doUploadFile(now time.Time, rawTeamId string, rawChannelId string, rawUserId string, rawFilename string, data []byte) (*model.FileInfo, bool, *model.AppError) {
 	filename := filepath.Base(rawFilename)
 	teamId := filepath.Base(rawTeamId)
 	channelId := filepath.Base(rawChannelId)
@@ -381,7 +428,7 @@ func (a *App) DoUploadFile(now time.Time, rawTeamId string, rawChannelId string,
 	info, err := model.GetInfoForBytes(filename, data)
 	if err != nil {
 		err.StatusCode = http.StatusBadRequest
-		return nil, err
+		return nil, false, err
 	}
 
 	if orientation, err := getImageOrientation(bytes.NewReader(data)); err == nil &&
@@ -394,6 +441,7 @@ func (a *App) DoUploadFile(now time.Time, rawTeamId string, rawChannelId string,
 
 	info.Id = model.NewId()
 	info.CreatorId = userId
+	info.ChannelId = channelId
 
 	pathPrefix := now.Format("20060102") + "/teams/" + teamId + "/channels/" + channelId + "/users/" + userId + "/" + info.Id + "/"
 	info.Path = pathPrefix + filename
@@ -402,7 +450,7 @@ func (a *App) DoUploadFile(now time.Time, rawTeamId string, rawChannelId string,
 		// Check dimensions before loading the whole thing into memory later on
 		if info.Width*info.Height > MaxImageSize {
 			err := model.NewAppError("uploadFile", "api.file.upload_file.large_image.app_error", map[string]interface{}{"Filename": filename}, "", http.StatusBadRequest)
-			return nil, err
+			return nil, false, err
 		}
 
 		nameWithoutExtension := filename[:strings.LastIndex(filename, ".")]
@@ -410,15 +458,104 @@ func (a *App) DoUploadFile(now time.Time, rawTeamId string, rawChannelId string,
 		info.ThumbnailPath = pathPrefix + nameWithoutExtension + "_thumb.jpg"
 	}
 
+	if stored := a.getStoredDuplicateFile(info); stored != nil {
+		info.Path = stored.Path
+
+		// Whether a file has a preview and thumbnail depends on its name as well, so they're only shared if both do
+		sharesImages := info.ThumbnailPath == "" || stored.ThumbnailPath != ""
+		if sharesImages && info.ThumbnailPath != "" {
+			info.ThumbnailPath = stored.ThumbnailPath
+			info.PreviewPath = stored.PreviewPath
+		}
+
+		a.sharedFilesLock.Lock()
+		defer a.sharedFilesLock.Unlock()
+
+		if result := <-a.Srv.Store.FileInfo().Save(info); result.Err != nil {
+			return nil, false, result.Err
+		}
+
+		// The stored file is removed along with the last info that refers to it, which could have happened before
+		// this one was saved
+		if exists, err := a.FileExists(info.Path); err == nil && exists {
+			return info, sharesImages, nil
+		}
+
+		if _, err := a.WriteFile(bytes.NewReader(data), info.Path); err != nil {
+			<-a.Srv.Store.FileInfo().PermanentDelete(info.Id)
+			return nil, false, err
+		}
+
+		return info, false, nil
+	}
+
 	if _, err := a.WriteFile(bytes.NewReader(data), info.Path); err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	if result := <-a.Srv.Store.FileInfo().Save(info); result.Err != nil {
+		return nil, false, result.Err
+	}
+
+	return info, false, nil
+}
+
+// getStoredDuplicateFile returns the info of a file in the file store with the same contents as the upload, or nil if
+// there isn't one. Only the uploader's own files are shared unless FileSettings.DeduplicateAcrossUsers is enabled.
+func (a *App) getStoredDuplicateFile(info *model.FileInfo) *model.FileInfo {
+	if info.Hash == "" {
+		return nil
+	}
+
+	creatorId := info.CreatorId
+	if *a.Config().FileSettings.DeduplicateAcrossUsers {
+		creatorId = ""
+	}
+
+	result := <-a.Srv.Store.FileInfo().GetByHash(info.Hash, info.Size, creatorId)
+	if result.Err != nil {
+		if result.Err.StatusCode != http.StatusNotFound {
+			mlog.Warn("Unable to look for a stored copy of an uploaded file", mlog.String("error", result.Err.Error()))
+		}
+		return nil
+	}
+
+	return result.Data.(*model.FileInfo)
+}
+
+// unreferencedFilePaths returns the paths in the file store of the deleted infos that no other info refers to, so
+// that a file that's shared by duplicate uploads is only removed along with the last of them. sharedFilesLock must be
+// held until the paths have been removed.
+func (a *App) unreferencedFilePaths(infos []*model.FileInfo) ([]string, *model.AppError) {
+	var paths []string
+	seen := map[string]bool{}
+	for _, info := range infos {
+		for _, path := range []string{info.Path, info.ThumbnailPath, info.PreviewPath} {
+			if path != "" && !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+
+	result := <-a.Srv.Store.FileInfo().GetReferencedPaths(paths)
+	if result.Err != nil {
 		return nil, result.Err
 	}
 
-	return info, nil
+	referenced := map[string]bool{}
+	for _, path := range result.Data.([]string) {
+		referenced[path] = true
+	}
+
+	var unreferenced []string
+	for _, path := range paths {
+		if !referenced[path] {
+			unreferenced = append(unreferenced, path)
+		}
+	}
+
+	return unreferenced, nil
 }
 
 func (a *App) HandleImages(previewPathList []string, thumbnailPathList []string, fileData [][]byte) {
@@ -562,3 +699,134 @@ func (a *App) GetFileInfo(fileId string) (*model.FileInfo, *model.AppError) {
 		return result.Data.(*model.FileInfo), nil
 	}
 }
+
+// GetFileContentType returns the type that the file, with the given contents, is served with. Files that were uploaded
+// before their type was detected from their contents have it detected and saved the first time that they're read.
+func (a *App) GetFileContentType(info *model.FileInfo, data []byte) string {
+	if info.ContentType == "" {
+		info.ContentType = model.DetectContentType(data)
+
+		if result := <-a.Srv.Store.FileInfo().SetContentType(info.Id, info.ContentType); result.Err != nil {
+			mlog.Warn(fmt.Sprintf("Failed to save the content type of a file: %v", result.Err.Error()), mlog.String("file_id", info.Id))
+		} else if info.PostId != "" {
+			a.Srv.Store.FileInfo().InvalidateFileInfosForPostCache(info.PostId)
+		}
+	}
+
+	return info.GetContentType()
+}
+
+// GetUserFiles returns a page of the files that a user has uploaded matching the filter, along with the count and
+// size of all of them.
+func (a *App) GetUserFiles(filter *model.FileInfoFilter, page, perPage int) (*model.UserFiles, *model.AppError) {
+	if err := filter.IsValid(); err != nil {
+		return nil, err
+	}
+
+	infosChan := a.Srv.Store.FileInfo().GetWithFilter(filter, page*perPage, perPage)
+	statsChan := a.Srv.Store.FileInfo().GetStatsWithFilter(filter)
+
+	infosResult := <-infosChan
+	if infosResult.Err != nil {
+		return nil, infosResult.Err
+	}
+
+	statsResult := <-statsChan
+	if statsResult.Err != nil {
+		return nil, statsResult.Err
+	}
+
+	return &model.UserFiles{
+		FileInfos:     infosResult.Data.([]*model.FileInfo),
+		FileInfoStats: *statsResult.Data.(*model.FileInfoStats),
+	}, nil
+}
+
+// DeleteUserFile deletes a file that the user uploaded, removing it from the post that it's attached to and from
+// the file store. Files attached to posts made by anyone else are left alone, since deleting them would change
+// someone else's post.
+func (a *App) DeleteUserFile(userId string, fileId string) *model.AppError {
+	info, err := a.GetFileInfo(fileId)
+	if err != nil {
+		return err
+	}
+
+	if info.DeleteAt != 0 {
+		return model.NewAppError("DeleteUserFile", "app.file.delete_user_file.not_found.app_error", nil, "file_id="+info.Id, http.StatusNotFound)
+	}
+
+	if info.CreatorId != userId {
+		return model.NewAppError("DeleteUserFile", "app.file.delete_user_file.not_owner.app_error", nil, "file_id="+info.Id, http.StatusForbidden)
+	}
+
+	if info.PostId != "" {
+		post, err := a.GetSinglePost(info.PostId)
+		if err != nil {
+			return err
+		}
+
+		if post.UserId != userId {
+			return model.NewAppError("DeleteUserFile", "app.file.delete_user_file.post_not_owned.app_error", nil, "file_id="+info.Id, http.StatusForbidden)
+		}
+
+		fileIds := model.StringArray{}
+		for _, id := range post.FileIds {
+			if id != info.Id {
+				fileIds = append(fileIds, id)
+			}
+		}
+
+		updated := &model.Post{}
+		*updated = *post
+		updated.FileIds = fileIds
+		if _, err := a.UpdatePost(updated, false); err != nil {
+			return err
+		}
+
+		a.Srv.Store.FileInfo().InvalidateFileInfosForPostCache(post.Id)
+	}
+
+	if result := <-a.Srv.Store.FileInfo().PermanentDelete(info.Id); result.Err != nil {
+		return result.Err
+	}
+
+	a.releaseFileStorageForFiles([]*model.FileInfo{info})
+
+	a.sharedFilesLock.Lock()
+	defer a.sharedFilesLock.Unlock()
+
+	// The file is already gone from the post, so anything left in the file store is only logged
+	paths, err := a.unreferencedFilePaths([]*model.FileInfo{info})
+	if err != nil {
+		mlog.Warn(fmt.Sprintf("Unable to check whether a deleted file is still used err=%v", err), mlog.String("file_id", info.Id))
+	}
+
+	for _, path := range paths {
+		if err := a.RemoveFile(path); err != nil {
+			mlog.Warn(fmt.Sprintf("Unable to remove a deleted file from the file store err=%v", err), mlog.String("path", path))
+		}
+	}
+
+	return nil
+}
+
+// GetFileTextPreview returns the start of a text file, escaped so that it can be shown as HTML. Files are checked
+// again when they're previewed, and ones that turn out not to be text are refused.
+func (a *App) GetFileTextPreview(info *model.FileInfo) (*model.FileTextPreview, *model.AppError) {
+	if !info.HasPreviewText {
+		return nil, model.NewAppError("GetFileTextPreview", "app.file.get_text_preview.not_text.app_error", nil, "file_id="+info.Id, http.StatusBadRequest)
+	}
+
+	data, err := a.ReadFile(info.Path)
+	if err != nil {
+		err.StatusCode = http.StatusNotFound
+		return nil, err
+	}
+
+	preview := model.NewFileTextPreview(info, data)
+	if preview == nil {
+		return nil, model.NewAppError("GetFileTextPreview", "app.file.get_text_preview.binary.app_error", nil, "file_id="+info.Id, http.StatusBadRequest)
+	}
+
+	return preview, nil
+}
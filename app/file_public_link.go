@@ -0,0 +1,121 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// CreateFilePublicLink saves a new public link to a file. Only files that have been posted can be shared.
+func (a *App) CreateFilePublicLink(link *model.FilePublicLink) (*model.FilePublicLink, *model.AppError) {
+	info, err := a.GetFileInfo(link.FileId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(info.PostId) == 0 {
+		return nil, model.NewAppError("CreateFilePublicLink", "api.file.get_public_link.no_post.app_error", nil, "file_id="+info.Id, http.StatusBadRequest)
+	}
+
+	post, err := a.GetSinglePost(info.PostId)
+	if err != nil {
+		return nil, err
+	}
+	link.ChannelId = post.ChannelId
+
+	result := <-a.Srv.Store.FilePublicLink().Save(link)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.FilePublicLink), nil
+}
+
+// GetOrCreateFilePublicLink returns a link to the file made by the user that never expires or runs out of
+// downloads, creating one if they don't have one already.
+func (a *App) GetOrCreateFilePublicLink(fileId string, userId string) (*model.FilePublicLink, *model.AppError) {
+	links, err := a.GetActiveFilePublicLinksForUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, link := range links {
+		if link.FileId == fileId && link.IsUnlimited() {
+			return link, nil
+		}
+	}
+
+	return a.CreateFilePublicLink(&model.FilePublicLink{FileId: fileId, CreatorId: userId})
+}
+
+func (a *App) GetFilePublicLink(linkId string) (*model.FilePublicLink, *model.AppError) {
+	result := <-a.Srv.Store.FilePublicLink().Get(linkId)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.FilePublicLink), nil
+}
+
+// GetActiveFilePublicLinksForUser returns the links made by the user that haven't been revoked, expired or used up.
+func (a *App) GetActiveFilePublicLinksForUser(userId string) ([]*model.FilePublicLink, *model.AppError) {
+	result := <-a.Srv.Store.FilePublicLink().GetActiveForUser(userId, model.GetMillis())
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.([]*model.FilePublicLink), nil
+}
+
+// GetActiveFilePublicLinksForChannel returns the links to files posted in the channel that haven't been revoked,
+// expired or used up.
+func (a *App) GetActiveFilePublicLinksForChannel(channelId string) ([]*model.FilePublicLink, *model.AppError) {
+	result := <-a.Srv.Store.FilePublicLink().GetActiveForChannel(channelId, model.GetMillis())
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.([]*model.FilePublicLink), nil
+}
+
+// RevokeFilePublicLink stops a link from being used any further. The file itself and any other links to it are
+// left as they are.
+func (a *App) RevokeFilePublicLink(linkId string) (*model.FilePublicLink, *model.AppError) {
+	link, err := a.GetFilePublicLink(linkId)
+	if err != nil {
+		return nil, err
+	}
+
+	if link.DeleteAt != 0 {
+		return link, nil
+	}
+
+	link.DeleteAt = model.GetMillis()
+	if result := <-a.Srv.Store.FilePublicLink().Revoke(link.Id, link.DeleteAt); result.Err != nil {
+		return nil, result.Err
+	}
+
+	return link, nil
+}
+
+// CountFilePublicLinkDownload uses up one of the downloads of the link with the given id, making sure that it's a
+// link to the given file. Links that have been revoked, have expired or have no downloads left return an error with
+// a status of Gone.
+func (a *App) CountFilePublicLinkDownload(linkId string, fileId string) *model.AppError {
+	link, err := a.GetFilePublicLink(linkId)
+	if err != nil || link.FileId != fileId {
+		return model.NewAppError("CountFilePublicLinkDownload", "api.file.get_file.public_invalid.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if result := <-a.Srv.Store.FilePublicLink().CountDownload(link.Id, model.GetMillis()); result.Err != nil {
+		if result.Err.Id == "store.sql_file_public_link.count_download.unavailable.app_error" {
+			return model.NewAppError("CountFilePublicLinkDownload", "app.file_public_link.unusable.app_error", nil, "id="+link.Id, http.StatusGone)
+		}
+		return result.Err
+	}
+
+	return nil
+}
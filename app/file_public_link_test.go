@@ -0,0 +1,72 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestFilePublicLinks(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	info, err := th.App.DoUploadFile(time.Now(), th.BasicTeam.Id, th.BasicChannel.Id, th.BasicUser.Id, "test.txt", []byte("data"))
+	require.Nil(t, err)
+	defer th.App.Srv.Store.FileInfo().PermanentDelete(info.Id)
+
+	_, err = th.App.CreateFilePublicLink(&model.FilePublicLink{FileId: info.Id, CreatorId: th.BasicUser.Id})
+	require.NotNil(t, err, "files that haven't been posted can't be shared")
+
+	post, err := th.App.CreatePostAsUser(&model.Post{
+		UserId:    th.BasicUser.Id,
+		ChannelId: th.BasicChannel.Id,
+		Message:   "file",
+		FileIds:   []string{info.Id},
+	})
+	require.Nil(t, err)
+	require.NotNil(t, post)
+
+	t.Run("get or create", func(t *testing.T) {
+		link, err := th.App.GetOrCreateFilePublicLink(info.Id, th.BasicUser.Id)
+		require.Nil(t, err)
+		assert.Equal(t, th.BasicChannel.Id, link.ChannelId)
+
+		again, err := th.App.GetOrCreateFilePublicLink(info.Id, th.BasicUser.Id)
+		require.Nil(t, err)
+		assert.Equal(t, link.Id, again.Id, "the unlimited link should be reused")
+
+		other, err := th.App.GetOrCreateFilePublicLink(info.Id, th.BasicUser2.Id)
+		require.Nil(t, err)
+		assert.NotEqual(t, link.Id, other.Id, "every user should get their own link")
+
+		_, err = th.App.RevokeFilePublicLink(link.Id)
+		require.Nil(t, err)
+
+		again, err = th.App.GetOrCreateFilePublicLink(info.Id, th.BasicUser.Id)
+		require.Nil(t, err)
+		assert.NotEqual(t, link.Id, again.Id, "revoked links shouldn't be reused")
+	})
+
+	t.Run("count downloads", func(t *testing.T) {
+		link, err := th.App.CreateFilePublicLink(&model.FilePublicLink{FileId: info.Id, CreatorId: th.BasicUser.Id, MaxDownloads: 1})
+		require.Nil(t, err)
+
+		err = th.App.CountFilePublicLinkDownload(link.Id, model.NewId())
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusBadRequest, err.StatusCode)
+
+		require.Nil(t, th.App.CountFilePublicLinkDownload(link.Id, info.Id))
+
+		err = th.App.CountFilePublicLinkDownload(link.Id, info.Id)
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusGone, err.StatusCode)
+	})
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const FILE_STORAGE_USAGE_RECONCILE_BATCH_SIZE = 1000
+
+// ReserveFileStorage counts a file that's about to be uploaded towards the storage used by its uploader and by the
+// team that it's being uploaded to, returning an error with a status of Request Entity Too Large if that would take
+// either of them past their quota. Files uploaded to direct and group channels only count towards the user. The
+// storage should be released again if the file isn't saved after all.
+func (a *App) ReserveFileStorage(userId string, teamId string, filename string, size int64) *model.AppError {
+	if size <= 0 {
+		return nil
+	}
+
+	userQuota := *a.Config().FileSettings.UserStorageQuota
+	teamQuota := *a.Config().FileSettings.TeamStorageQuota
+
+	result := <-a.Srv.Store.FileStorageUsage().Reserve(userId, teamId, size, userQuota, teamQuota)
+	if result.Err == nil {
+		return nil
+	}
+
+	id := userId
+	quota := userQuota
+	errorId := "app.file_storage_usage.user_quota_exceeded.app_error"
+	switch result.Err.Id {
+	case "store.sql_file_storage_usage.reserve.user_quota_exceeded.app_error":
+	case "store.sql_file_storage_usage.reserve.team_quota_exceeded.app_error":
+		id = teamId
+		quota = teamQuota
+		errorId = "app.file_storage_usage.team_quota_exceeded.app_error"
+	default:
+		return result.Err
+	}
+
+	var usage int64
+	if usageResult := <-a.Srv.Store.FileStorageUsage().Get(id); usageResult.Err == nil {
+		usage = usageResult.Data.(*model.FileStorageUsage).Bytes
+	}
+
+	return model.NewAppError("ReserveFileStorage", errorId, map[string]interface{}{
+		"Filename": filename,
+		"Usage":    usage,
+		"Quota":    quota,
+	}, fmt.Sprintf("id=%v usage=%v quota=%v size=%v", id, usage, quota, size), http.StatusRequestEntityTooLarge)
+}
+
+// ReleaseFileStorage stops counting a file towards the storage used by its uploader and by a team, such as when the
+// file is deleted. Failures are only logged since the usage is reconciled with the files every night anyway.
+func (a *App) ReleaseFileStorage(userId string, teamId string, size int64) {
+	if size <= 0 {
+		return
+	}
+
+	if result := <-a.Srv.Store.FileStorageUsage().Release(userId, teamId, size); result.Err != nil {
+		mlog.Warn(fmt.Sprintf("Failed to release file storage user_id=%v team_id=%v size=%v err=%v", userId, teamId, size, result.Err))
+	}
+}
+
+// fileStorageTeamId returns the team that a file counts towards, which is the team of the channel that it was
+// uploaded to or, for files from before that was saved, the channel that it was posted in.
+func (a *App) fileStorageTeamId(info *model.FileInfo) string {
+	channelId := info.ChannelId
+	if channelId == "" && info.PostId != "" {
+		if post, err := a.GetSinglePost(info.PostId); err == nil {
+			channelId = post.ChannelId
+		}
+	}
+
+	if channelId == "" {
+		return ""
+	}
+
+	channel, err := a.GetChannel(channelId)
+	if err != nil {
+		return ""
+	}
+
+	return channel.TeamId
+}
+
+// releaseFileStorageForFiles releases the storage used by files that have been deleted.
+func (a *App) releaseFileStorageForFiles(infos []*model.FileInfo) {
+	for _, info := range infos {
+		a.ReleaseFileStorage(info.CreatorId, a.fileStorageTeamId(info), info.Size)
+	}
+}
+
+// GetTopFileStorageUsage returns a page of the users or teams using the most storage, with the biggest first.
+func (a *App) GetTopFileStorageUsage(usageType string, page int, perPage int) ([]*model.FileStorageUsage, *model.AppError) {
+	if !model.IsValidFileStorageUsageType(usageType) {
+		return nil, model.NewAppError("GetTopFileStorageUsage", "app.file_storage_usage.invalid_type.app_error", nil, "type="+usageType, http.StatusBadRequest)
+	}
+
+	result := <-a.Srv.Store.FileStorageUsage().GetTop(usageType, page*perPage, perPage)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.([]*model.FileStorageUsage), nil
+}
+
+// SetUserFileStorageExempt sets whether a user can upload files past the user and team storage quotas. Their
+// uploads are still counted either way.
+func (a *App) SetUserFileStorageExempt(userId string, exempt bool) *model.AppError {
+	if _, err := a.GetUser(userId); err != nil {
+		return err
+	}
+
+	if result := <-a.Srv.Store.FileStorageUsage().SetExempt(userId, exempt); result.Err != nil {
+		return result.Err
+	}
+
+	return nil
+}
+
+// ReconcileFileStorageUsage adds up the files of every user and team a batch at a time, fixing any usage that's
+// drifted, such as from uploads that were interrupted. It returns the number of users and teams that were fixed.
+func (a *App) ReconcileFileStorageUsage() (int64, *model.AppError) {
+	var corrected int64
+	for _, usageType := range []string{model.FILE_STORAGE_USAGE_TYPE_USER, model.FILE_STORAGE_USAGE_TYPE_TEAM} {
+		afterId := ""
+		for {
+			result := <-a.Srv.Store.FileStorageUsage().Reconcile(usageType, afterId, FILE_STORAGE_USAGE_RECONCILE_BATCH_SIZE)
+			if result.Err != nil {
+				return corrected, result.Err
+			}
+
+			batch := result.Data.(*model.FileStorageUsageBatch)
+			corrected += batch.Corrected
+			if batch.LastId == "" {
+				break
+			}
+
+			afterId = batch.LastId
+		}
+	}
+
+	return corrected, nil
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func getFileStorageUsageBytes(t *testing.T, th *TestHelper, id string) int64 {
+	result := <-th.App.Srv.Store.FileStorageUsage().Get(id)
+	require.Nil(t, result.Err)
+	return result.Data.(*model.FileStorageUsage).Bytes
+}
+
+func uploadTestFile(th *TestHelper, channel *model.Channel, user *model.User, size int) (*model.FileInfo, *model.AppError) {
+	data := bytes.Repeat([]byte("a"), size)
+	response, err := th.App.UploadFiles("noteam", channel.Id, user.Id, []io.ReadCloser{ioutil.NopCloser(bytes.NewReader(data))}, []string{"test.txt"}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return response.FileInfos[0], nil
+}
+
+func TestFileStorageQuotas(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.FileSettings.UserStorageQuota = 100
+		*cfg.FileSettings.TeamStorageQuota = 150
+	})
+
+	info, err := uploadTestFile(th, th.BasicChannel, th.BasicUser, 60)
+	require.Nil(t, err)
+	assert.Equal(t, th.BasicChannel.Id, info.ChannelId)
+	assert.Equal(t, int64(60), getFileStorageUsageBytes(t, th, th.BasicUser.Id))
+	assert.Equal(t, int64(60), getFileStorageUsageBytes(t, th, th.BasicTeam.Id))
+
+	t.Run("user quota", func(t *testing.T) {
+		_, err := uploadTestFile(th, th.BasicChannel, th.BasicUser, 50)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.file_storage_usage.user_quota_exceeded.app_error", err.Id)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, err.StatusCode)
+		assert.Contains(t, err.DetailedError, "usage=60 quota=100")
+		assert.Equal(t, int64(60), getFileStorageUsageBytes(t, th, th.BasicUser.Id))
+	})
+
+	t.Run("team quota", func(t *testing.T) {
+		_, err := uploadTestFile(th, th.BasicChannel, th.BasicUser2, 80)
+		require.Nil(t, err)
+
+		_, err = uploadTestFile(th, th.BasicChannel, th.BasicUser2, 20)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.file_storage_usage.team_quota_exceeded.app_error", err.Id)
+		assert.Contains(t, err.DetailedError, "usage=140 quota=150")
+		assert.Equal(t, int64(80), getFileStorageUsageBytes(t, th, th.BasicUser2.Id), "the user's usage should be left as it was")
+
+		// Direct messages only count towards the user
+		_, err = uploadTestFile(th, th.CreateDmChannel(th.BasicUser), th.BasicUser2, 20)
+		require.Nil(t, err)
+		assert.Equal(t, int64(100), getFileStorageUsageBytes(t, th, th.BasicUser2.Id))
+		assert.Equal(t, int64(140), getFileStorageUsageBytes(t, th, th.BasicTeam.Id))
+	})
+
+	t.Run("exemption", func(t *testing.T) {
+		require.Nil(t, th.App.SetUserFileStorageExempt(th.BasicUser.Id, true))
+
+		exempt, err := uploadTestFile(th, th.BasicChannel, th.BasicUser, 500)
+		require.Nil(t, err)
+		assert.Equal(t, int64(560), getFileStorageUsageBytes(t, th, th.BasicUser.Id), "exempt users should still be counted")
+		assert.Equal(t, int64(640), getFileStorageUsageBytes(t, th, th.BasicTeam.Id))
+
+		require.Nil(t, th.App.SetUserFileStorageExempt(th.BasicUser.Id, false))
+		_, err = uploadTestFile(th, th.BasicChannel, th.BasicUser, 1)
+		require.NotNil(t, err)
+
+		require.Nil(t, th.App.DeleteUserFile(th.BasicUser.Id, exempt.Id))
+		assert.Equal(t, int64(60), getFileStorageUsageBytes(t, th, th.BasicUser.Id))
+		assert.Equal(t, int64(140), getFileStorageUsageBytes(t, th, th.BasicTeam.Id))
+
+		assert.NotNil(t, th.App.SetUserFileStorageExempt(model.NewId(), true))
+	})
+
+	t.Run("deleting", func(t *testing.T) {
+		require.Nil(t, th.App.DeleteUserFile(th.BasicUser.Id, info.Id))
+		assert.Equal(t, int64(0), getFileStorageUsageBytes(t, th, th.BasicUser.Id))
+		assert.Equal(t, int64(80), getFileStorageUsageBytes(t, th, th.BasicTeam.Id))
+
+		_, err := uploadTestFile(th, th.BasicChannel, th.BasicUser, 70)
+		require.Nil(t, err, "deleted files should free up the quota")
+	})
+}
+
+func TestFileStorageQuotasConcurrentUploads(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.FileSettings.UserStorageQuota = 95
+	})
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	uploaded := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if _, err := uploadTestFile(th, th.BasicChannel, th.BasicUser, 10); err == nil {
+				lock.Lock()
+				uploaded++
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 9, uploaded, "only the uploads that fit in the quota should get through")
+	assert.Equal(t, int64(90), getFileStorageUsageBytes(t, th, th.BasicUser.Id))
+
+	files, err := th.App.GetUserFiles(&model.FileInfoFilter{CreatorId: th.BasicUser.Id}, 0, 100)
+	require.Nil(t, err)
+	assert.Equal(t, int64(90), files.TotalSize)
+}
+
+func TestReconcileFileStorageUsage(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	_, err := uploadTestFile(th, th.BasicChannel, th.BasicUser, 30)
+	require.Nil(t, err)
+
+	// An upload that was interrupted after it was counted
+	require.Nil(t, (<-th.App.Srv.Store.FileStorageUsage().Reserve(th.BasicUser.Id, th.BasicTeam.Id, 1000, 0, 0)).Err)
+
+	corrected, err := th.App.ReconcileFileStorageUsage()
+	require.Nil(t, err)
+	assert.True(t, corrected >= 2)
+	assert.Equal(t, int64(30), getFileStorageUsageBytes(t, th, th.BasicUser.Id))
+	assert.Equal(t, int64(30), getFileStorageUsageBytes(t, th, th.BasicTeam.Id))
+
+	usages, err := th.App.GetTopFileStorageUsage(model.FILE_STORAGE_USAGE_TYPE_TEAM, 0, 1000)
+	require.Nil(t, err)
+	found := false
+	for _, usage := range usages {
+		if usage.Id == th.BasicTeam.Id {
+			found = true
+			assert.Equal(t, int64(30), usage.Bytes)
+		}
+	}
+	assert.True(t, found)
+
+	_, err = th.App.GetTopFileStorageUsage("channel", 0, 10)
+	assert.NotNil(t, err)
+}
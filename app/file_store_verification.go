@@ -0,0 +1,250 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+const (
+	FILE_STORE_VERIFICATION_BATCH_SIZE = 1000
+
+	// FILE_STORE_VERIFICATION_CONCURRENCY is how many files are checked for in the file store at once by default.
+	FILE_STORE_VERIFICATION_CONCURRENCY = 8
+)
+
+// fileStoreUploadDirectory matches the directories that files are uploaded to, which are named by the day that they
+// were uploaded on. Everything else in the file store, such as profile pictures and emoji, doesn't have file infos.
+var fileStoreUploadDirectory = regexp.MustCompile(`^[0-9]{8}$`)
+
+// VerifyFileStore checks that the file of every file info is in the file store, and that every object in the
+// directories that files are uploaded to is the file, thumbnail or preview of a file info. With repair, the files
+// that are missing are marked as missing so that clients show a placeholder for them, and the objects that are
+// dangling are deleted. progress, if given, is called after each batch, and the verification stops if it returns
+// false.
+func (a *App) VerifyFileStore(repair bool, concurrency int, progress func(result *model.FileStoreVerificationResult) bool) (*model.FileStoreVerificationResult, *model.AppError) {
+	if progress == nil {
+		progress = func(*model.FileStoreVerificationResult) bool { return true }
+	}
+
+	result := &model.FileStoreVerificationResult{
+		Repair:         repair,
+		MissingFileIds: []string{},
+		DanglingPaths:  []string{},
+	}
+
+	backend, err := a.FileBackend()
+	if err != nil {
+		return result, err
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	if stopped, err := a.verifyFileInfos(backend, result, concurrency, progress); err != nil || stopped {
+		return result, err
+	}
+
+	return result, a.verifyFileStoreObjects(backend, result, progress)
+}
+
+// verifyFileInfos checks for the file of each file info in the file store, returning true if progress stopped it.
+func (a *App) verifyFileInfos(backend utils.FileBackend, result *model.FileStoreVerificationResult, concurrency int, progress func(result *model.FileStoreVerificationResult) bool) (bool, *model.AppError) {
+	afterId := ""
+	for {
+		batchResult := <-a.Srv.Store.FileInfo().GetBatchAfterId(afterId, FILE_STORE_VERIFICATION_BATCH_SIZE)
+		if batchResult.Err != nil {
+			return false, batchResult.Err
+		}
+
+		infos := batchResult.Data.([]*model.FileInfo)
+		if len(infos) == 0 {
+			return false, nil
+		}
+		afterId = infos[len(infos)-1].Id
+
+		exists, err := filesExist(backend, infos, concurrency)
+		if err != nil {
+			return false, err
+		}
+
+		var missing, found []string
+		changedPostIds := map[string]bool{}
+		for i, info := range infos {
+			result.FilesChecked++
+
+			if !exists[i] {
+				result.FilesMissing++
+				if len(result.MissingFileIds) < model.FILE_STORE_VERIFICATION_MAX_REPORTED {
+					result.MissingFileIds = append(result.MissingFileIds, info.Id)
+				}
+
+				if info.MissingAt == 0 {
+					missing = append(missing, info.Id)
+					changedPostIds[info.PostId] = true
+				}
+			} else if info.MissingAt != 0 {
+				result.FilesFound++
+				found = append(found, info.Id)
+				changedPostIds[info.PostId] = true
+			}
+		}
+
+		if result.Repair {
+			if setResult := <-a.Srv.Store.FileInfo().SetMissingAt(missing, model.GetMillis()); setResult.Err != nil {
+				return false, setResult.Err
+			}
+			result.FilesMarked += int64(len(missing))
+
+			if setResult := <-a.Srv.Store.FileInfo().SetMissingAt(found, 0); setResult.Err != nil {
+				return false, setResult.Err
+			}
+
+			for postId := range changedPostIds {
+				if postId != "" {
+					a.Srv.Store.FileInfo().InvalidateFileInfosForPostCache(postId)
+				}
+			}
+		}
+
+		if !progress(result) {
+			return true, nil
+		}
+	}
+}
+
+// filesExist checks for the files of the infos in the file store, with up to concurrency of them being checked for at
+// once.
+func filesExist(backend utils.FileBackend, infos []*model.FileInfo, concurrency int) ([]bool, *model.AppError) {
+	exists := make([]bool, len(infos))
+	errs := make([]*model.AppError, len(infos))
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, concurrency)
+	for i, info := range infos {
+		if info.Path == "" {
+			exists[i] = true
+			continue
+		}
+
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, path string) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+
+			exists[i], errs[i] = backend.FileExists(path)
+		}(i, info.Path)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return exists, nil
+}
+
+// verifyFileStoreObjects checks for a file info for each object in the directories that files are uploaded to.
+// Directories from the last day are skipped, since a file's info is only saved after it's been uploaded.
+func (a *App) verifyFileStoreObjects(backend utils.FileBackend, result *model.FileStoreVerificationResult, progress func(result *model.FileStoreVerificationResult) bool) *model.AppError {
+	directories, err := backend.ListDirectory("")
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -1).Format("20060102")
+
+	for _, directory := range *directories {
+		if !fileStoreUploadDirectory.MatchString(directory) || directory >= cutoff {
+			continue
+		}
+
+		stopped := false
+		var batchErr *model.AppError
+		var paths []string
+		if err := backend.WalkFiles(directory, func(path string) bool {
+			paths = append(paths, path)
+			if len(paths) < FILE_STORE_VERIFICATION_BATCH_SIZE {
+				return true
+			}
+
+			if batchErr = a.verifyFileStoreObjectBatch(backend, paths, result); batchErr != nil {
+				return false
+			}
+			paths = nil
+
+			stopped = !progress(result)
+			return !stopped
+		}); err != nil {
+			return err
+		}
+
+		if batchErr != nil {
+			return batchErr
+		} else if stopped {
+			return nil
+		}
+
+		if len(paths) > 0 {
+			if err := a.verifyFileStoreObjectBatch(backend, paths, result); err != nil {
+				return err
+			}
+
+			if !progress(result) {
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+func (a *App) verifyFileStoreObjectBatch(backend utils.FileBackend, paths []string, result *model.FileStoreVerificationResult) *model.AppError {
+	if result.Repair {
+		a.sharedFilesLock.Lock()
+		defer a.sharedFilesLock.Unlock()
+	}
+
+	referencedResult := <-a.Srv.Store.FileInfo().GetReferencedPaths(paths)
+	if referencedResult.Err != nil {
+		return referencedResult.Err
+	}
+
+	referenced := map[string]bool{}
+	for _, path := range referencedResult.Data.([]string) {
+		referenced[path] = true
+	}
+
+	for _, path := range paths {
+		result.ObjectsChecked++
+		if referenced[path] {
+			continue
+		}
+
+		result.ObjectsDangling++
+		if len(result.DanglingPaths) < model.FILE_STORE_VERIFICATION_MAX_REPORTED {
+			result.DanglingPaths = append(result.DanglingPaths, path)
+		}
+
+		if result.Repair {
+			if err := backend.RemoveFile(path); err != nil {
+				return err
+			}
+			result.ObjectsDeleted++
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestVerifyFileStore(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	dir, ioErr := ioutil.TempDir("", "")
+	require.NoError(t, ioErr)
+	defer os.RemoveAll(dir)
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.FileSettings.DriverName = model.IMAGE_DRIVER_LOCAL
+		cfg.FileSettings.Directory = dir
+	})
+
+	// Files uploaded in the last day aren't checked, so these are uploaded before then
+	uploadedAt := time.Now().AddDate(0, 0, -2)
+
+	kept, err := th.App.DoUploadFile(uploadedAt, th.BasicTeam.Id, th.BasicChannel.Id, th.BasicUser.Id, "kept.txt", []byte("data"))
+	require.Nil(t, err)
+	missing, err := th.App.DoUploadFile(uploadedAt, th.BasicTeam.Id, th.BasicChannel.Id, th.BasicUser.Id, "missing.txt", []byte("missing"))
+	require.Nil(t, err)
+	require.Nil(t, th.App.RemoveFile(missing.Path))
+
+	danglingPath := uploadedAt.Format("20060102") + "/teams/" + th.BasicTeam.Id + "/dangling.txt"
+	recentPath := time.Now().Format("20060102") + "/teams/" + th.BasicTeam.Id + "/recent.txt"
+	profilePath := "users/" + th.BasicUser.Id + "/profile.png"
+	for _, path := range []string{danglingPath, recentPath, profilePath} {
+		_, err = th.App.WriteFile(bytes.NewReader([]byte("data")), path)
+		require.Nil(t, err)
+	}
+
+	fileExists := func(path string) bool {
+		backend, err := th.App.FileBackend()
+		require.Nil(t, err)
+
+		exists, err := backend.FileExists(path)
+		require.Nil(t, err)
+		return exists
+	}
+
+	t.Run("stopped by progress", func(t *testing.T) {
+		progressed := 0
+		result, err := th.App.VerifyFileStore(false, 2, func(result *model.FileStoreVerificationResult) bool {
+			progressed++
+			return false
+		})
+		require.Nil(t, err)
+		assert.Equal(t, 1, progressed)
+		assert.EqualValues(t, 0, result.ObjectsChecked, "the objects shouldn't have been checked after stopping")
+	})
+
+	t.Run("detection", func(t *testing.T) {
+		result, err := th.App.VerifyFileStore(false, 2, nil)
+		require.Nil(t, err)
+
+		assert.True(t, result.FilesChecked >= 2)
+		assert.True(t, result.FilesMissing >= 1)
+		assert.EqualValues(t, 0, result.FilesMarked)
+
+		assert.EqualValues(t, 1, result.ObjectsDangling)
+		assert.Equal(t, []string{danglingPath}, result.DanglingPaths)
+		assert.EqualValues(t, 0, result.ObjectsDeleted)
+
+		info, err := th.App.GetFileInfo(missing.Id)
+		require.Nil(t, err)
+		assert.EqualValues(t, 0, info.MissingAt, "nothing should be marked without repair")
+		assert.True(t, fileExists(danglingPath), "nothing should be deleted without repair")
+	})
+
+	t.Run("repair", func(t *testing.T) {
+		result, err := th.App.VerifyFileStore(true, 2, nil)
+		require.Nil(t, err)
+
+		assert.True(t, result.FilesMarked >= 1)
+		assert.EqualValues(t, 1, result.ObjectsDeleted)
+
+		info, err := th.App.GetFileInfo(missing.Id)
+		require.Nil(t, err)
+		assert.NotEqual(t, int64(0), info.MissingAt)
+
+		info, err = th.App.GetFileInfo(kept.Id)
+		require.Nil(t, err)
+		assert.EqualValues(t, 0, info.MissingAt)
+
+		assert.False(t, fileExists(danglingPath))
+		assert.True(t, fileExists(kept.Path))
+		assert.True(t, fileExists(recentPath), "objects uploaded in the last day shouldn't be deleted")
+		assert.True(t, fileExists(profilePath), "objects outside of the upload directories shouldn't be deleted")
+
+		result, err = th.App.VerifyFileStore(true, 2, nil)
+		require.Nil(t, err)
+		assert.EqualValues(t, 0, result.ObjectsDangling)
+	})
+
+	t.Run("found again", func(t *testing.T) {
+		_, err := th.App.WriteFile(bytes.NewReader([]byte("data")), missing.Path)
+		require.Nil(t, err)
+
+		result, err := th.App.VerifyFileStore(true, 2, nil)
+		require.Nil(t, err)
+		assert.True(t, result.FilesFound >= 1)
+
+		info, err := th.App.GetFileInfo(missing.Id)
+		require.Nil(t, err)
+		assert.EqualValues(t, 0, info.MissingAt)
+	})
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
 	"github.com/mattermost/mattermost-server/utils"
 )
 
@@ -48,9 +49,8 @@ func TestDoUploadFile(t *testing.T) {
 	channelId := model.NewId()
 	userId := model.NewId()
 	filename := "test"
-	data := []byte("abcd")
 
-	info1, err := th.App.DoUploadFile(time.Date(2007, 2, 4, 1, 2, 3, 4, time.Local), teamId, channelId, userId, filename, data)
+	info1, err := th.App.DoUploadFile(time.Date(2007, 2, 4, 1, 2, 3, 4, time.Local), teamId, channelId, userId, filename, []byte("abcd1"))
 	if err != nil {
 		t.Fatal(err)
 	} else {
@@ -64,7 +64,7 @@ func TestDoUploadFile(t *testing.T) {
 		t.Fatal("stored file at incorrect path", info1.Path)
 	}
 
-	info2, err := th.App.DoUploadFile(time.Date(2007, 2, 4, 1, 2, 3, 4, time.Local), teamId, channelId, userId, filename, data)
+	info2, err := th.App.DoUploadFile(time.Date(2007, 2, 4, 1, 2, 3, 4, time.Local), teamId, channelId, userId, filename, []byte("abcd2"))
 	if err != nil {
 		t.Fatal(err)
 	} else {
@@ -78,7 +78,7 @@ func TestDoUploadFile(t *testing.T) {
 		t.Fatal("stored file at incorrect path", info2.Path)
 	}
 
-	info3, err := th.App.DoUploadFile(time.Date(2008, 3, 5, 1, 2, 3, 4, time.Local), teamId, channelId, userId, filename, data)
+	info3, err := th.App.DoUploadFile(time.Date(2008, 3, 5, 1, 2, 3, 4, time.Local), teamId, channelId, userId, filename, []byte("abcd3"))
 	if err != nil {
 		t.Fatal(err)
 	} else {
@@ -92,7 +92,7 @@ func TestDoUploadFile(t *testing.T) {
 		t.Fatal("stored file at incorrect path", info3.Path)
 	}
 
-	info4, err := th.App.DoUploadFile(time.Date(2009, 3, 5, 1, 2, 3, 4, time.Local), "../../"+teamId, "../../"+channelId, "../../"+userId, "../../"+filename, data)
+	info4, err := th.App.DoUploadFile(time.Date(2009, 3, 5, 1, 2, 3, 4, time.Local), "../../"+teamId, "../../"+channelId, "../../"+userId, "../../"+filename, []byte("abcd4"))
 	if err != nil {
 		t.Fatal(err)
 	} else {
@@ -107,6 +107,119 @@ func TestDoUploadFile(t *testing.T) {
 	}
 }
 
+func TestUploadDuplicateFiles(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	data := []byte("report " + model.NewId())
+	upload := func(channel *model.Channel, user *model.User) *model.FileInfo {
+		info, err := th.App.DoUploadFile(time.Now(), th.BasicTeam.Id, channel.Id, user.Id, "report.txt", data)
+		require.Nil(t, err)
+		return info
+	}
+	stored := func(path string) bool {
+		exists, err := th.App.FileExists(path)
+		require.Nil(t, err)
+		return exists
+	}
+
+	first := upload(th.BasicChannel, th.BasicUser)
+	second := upload(th.BasicChannel, th.BasicUser)
+	assert.NotEqual(t, first.Id, second.Id)
+	assert.Equal(t, first.Hash, second.Hash)
+	assert.Equal(t, first.Path, second.Path, "the duplicate should share the stored file")
+
+	t.Run("other users", func(t *testing.T) {
+		other := upload(th.BasicChannel, th.BasicUser2)
+		assert.NotEqual(t, first.Path, other.Path)
+
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.FileSettings.DeduplicateAcrossUsers = true })
+		defer th.App.UpdateConfig(func(cfg *model.Config) { *cfg.FileSettings.DeduplicateAcrossUsers = false })
+
+		shared := upload(th.BasicChannel, th.BasicUser2)
+		assert.Equal(t, first.Path, shared.Path, "the earliest stored file should be shared")
+
+		require.Nil(t, th.App.DeleteUserFile(th.BasicUser2.Id, other.Id))
+		assert.False(t, stored(other.Path))
+		require.Nil(t, th.App.DeleteUserFile(th.BasicUser2.Id, shared.Id))
+		assert.True(t, stored(first.Path))
+	})
+
+	t.Run("named as an image", func(t *testing.T) {
+		image, err := th.App.DoUploadFile(time.Now(), th.BasicTeam.Id, th.BasicChannel.Id, th.BasicUser.Id, "report.png", data)
+		require.Nil(t, err)
+		defer th.App.DeleteUserFile(th.BasicUser.Id, image.Id)
+
+		assert.Equal(t, first.Path, image.Path)
+		assert.NotEmpty(t, image.ThumbnailPath, "the stored file doesn't have a thumbnail to share")
+	})
+
+	t.Run("channel deletion", func(t *testing.T) {
+		channel := th.CreateChannel(th.BasicTeam)
+		upload(channel, th.BasicUser)
+
+		deleted, err := th.App.permanentDeleteChannelFiles(channel)
+		require.Nil(t, err)
+		assert.Equal(t, int64(1), deleted)
+		assert.True(t, stored(first.Path), "the file is still used in another channel")
+	})
+
+	t.Run("retention", func(t *testing.T) {
+		retained := upload(th.BasicChannel, th.BasicUser)
+		require.Nil(t, (<-th.App.Srv.Store.FileInfo().PermanentDelete(retained.Id)).Err)
+
+		referenced := store.Must(th.App.Srv.Store.FileInfo().GetReferencedPaths([]string{first.Path})).([]string)
+		assert.Equal(t, []string{first.Path}, referenced, "verifying the file store shouldn't remove the file")
+	})
+
+	require.Nil(t, th.App.DeleteUserFile(th.BasicUser.Id, first.Id))
+	assert.True(t, stored(first.Path), "the file should be kept for the other upload")
+
+	require.Nil(t, th.App.DeleteUserFile(th.BasicUser.Id, second.Id))
+	assert.False(t, stored(first.Path), "the file should be removed along with its last upload")
+
+	t.Run("the stored file is already gone", func(t *testing.T) {
+		gone := upload(th.BasicChannel, th.BasicUser)
+		defer th.App.DeleteUserFile(th.BasicUser.Id, gone.Id)
+		require.Nil(t, th.App.RemoveFile(gone.Path))
+
+		again := upload(th.BasicChannel, th.BasicUser)
+		defer th.App.DeleteUserFile(th.BasicUser.Id, again.Id)
+		assert.Equal(t, gone.Path, again.Path)
+
+		saved, err := th.App.ReadFile(again.Path)
+		require.Nil(t, err)
+		assert.Equal(t, data, saved, "the file should be written again")
+	})
+}
+
+func TestGetFileContentType(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	data := []byte("<html><body><script>alert('hello')</script></body></html>")
+
+	info, err := th.App.DoUploadFile(time.Now(), th.BasicTeam.Id, th.BasicChannel.Id, th.BasicUser.Id, "image.png", data)
+	require.Nil(t, err)
+	defer th.App.RemoveFile(info.Path)
+	assert.Equal(t, "image/png", info.MimeType)
+	assert.Equal(t, "text/html; charset=utf-8", info.ContentType, "the type should be detected when the file is uploaded")
+	assert.Equal(t, "text/html; charset=utf-8", th.App.GetFileContentType(info, data))
+
+	t.Run("uploaded before detection", func(t *testing.T) {
+		require.Nil(t, (<-th.App.Srv.Store.FileInfo().SetContentType(info.Id, "")).Err)
+		info, err := th.App.GetFileInfo(info.Id)
+		require.Nil(t, err)
+		require.Equal(t, "", info.ContentType)
+
+		assert.Equal(t, "text/html; charset=utf-8", th.App.GetFileContentType(info, data))
+
+		info, err = th.App.GetFileInfo(info.Id)
+		require.Nil(t, err)
+		assert.Equal(t, "text/html; charset=utf-8", info.ContentType, "the detected type should be saved")
+	})
+}
+
 func TestGetInfoForFilename(t *testing.T) {
 	th := Setup().InitBasic()
 	defer th.TearDown()
@@ -161,3 +274,50 @@ func TestMigrateFilenamesToFileInfos(t *testing.T) {
 	infos = th.App.MigrateFilenamesToFileInfos(rpost)
 	assert.Equal(t, 1, len(infos))
 }
+
+func TestDeleteUserFile(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	upload := func(data string) *model.FileInfo {
+		info, err := th.App.DoUploadFile(time.Now(), th.BasicTeam.Id, th.BasicChannel.Id, th.BasicUser.Id, "test", []byte(data))
+		require.Nil(t, err)
+		return info
+	}
+
+	kept := upload("abcd")
+	defer func() {
+		<-th.App.Srv.Store.FileInfo().PermanentDelete(kept.Id)
+		th.App.RemoveFile(kept.Path)
+	}()
+	deleted := upload("efgh")
+
+	post, err := th.App.CreatePostAsUser(&model.Post{
+		ChannelId: th.BasicChannel.Id,
+		UserId:    th.BasicUser.Id,
+		Message:   "files",
+		FileIds:   model.StringArray{kept.Id, deleted.Id},
+	})
+	require.Nil(t, err)
+
+	err = th.App.DeleteUserFile(th.BasicUser2.Id, deleted.Id)
+	require.NotNil(t, err)
+	assert.Equal(t, "app.file.delete_user_file.not_owner.app_error", err.Id)
+
+	require.Nil(t, th.App.DeleteUserFile(th.BasicUser.Id, deleted.Id))
+
+	post, err = th.App.GetSinglePost(post.Id)
+	require.Nil(t, err)
+	assert.Equal(t, model.StringArray{kept.Id}, post.FileIds)
+
+	_, err = th.App.GetFileInfo(deleted.Id)
+	assert.NotNil(t, err, "the file's info should be deleted")
+
+	_, err = th.App.ReadFile(deleted.Path)
+	assert.NotNil(t, err, "the file should be removed from the file store")
+
+	infos, err := th.App.GetFileInfosForPost(post.Id, false)
+	require.Nil(t, err)
+	require.Len(t, infos, 1)
+	assert.Equal(t, kept.Id, infos[0].Id)
+}
@@ -0,0 +1,254 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+// CheckCanManageGroupChannelMembers returns an error unless the user is allowed to add people to or remove others from
+// the group channel by TeamSettings.RestrictGroupChannelManageMembers. Only members of the channel can, and only its
+// creator can when it's restricted to them, so nobody can manage a channel made before creators were recorded then.
+func (a *App) CheckCanManageGroupChannelMembers(channel *model.Channel, userId string) *model.AppError {
+	allowed := false
+	switch *a.Config().TeamSettings.RestrictGroupChannelManageMembers {
+	case model.GROUP_CHANNEL_MANAGE_MEMBERS_ANY:
+		allowed = true
+	case model.GROUP_CHANNEL_MANAGE_MEMBERS_CREATOR:
+		allowed = channel.CreatorId == userId
+	}
+
+	if allowed {
+		if result := <-a.Srv.Store.Channel().GetMember(channel.Id, userId); result.Err != nil {
+			allowed = false
+		}
+	}
+
+	if !allowed {
+		return model.NewAppError("CheckCanManageGroupChannelMembers", "app.channel.group_members.permission.app_error", nil, "channel_id="+channel.Id+", user_id="+userId, http.StatusForbidden)
+	}
+
+	return nil
+}
+
+// AddUsersToGroupChannel adds the users to the group channel on behalf of actorId, keeping its id and so its history
+// while giving it the name and display name that a new group channel with the same members would have.
+func (a *App) AddUsersToGroupChannel(channel *model.Channel, userIds []string, actorId string) (*model.Channel, *model.AppError) {
+	if channel.Type != model.CHANNEL_GROUP {
+		return nil, model.NewAppError("AddUsersToGroupChannel", "app.channel.group_members.type.app_error", nil, "channel_id="+channel.Id, http.StatusBadRequest)
+	}
+
+	if err := a.CheckCanManageGroupChannelMembers(channel, actorId); err != nil {
+		return nil, err
+	}
+
+	memberIds, err := a.getGroupChannelMemberIds(channel)
+	if err != nil {
+		return nil, err
+	}
+
+	var newIds []string
+	for _, userId := range userIds {
+		if !model.IsValidId(userId) {
+			return nil, model.NewAppError("AddUsersToGroupChannel", "app.channel.group_members.bad_user.app_error", nil, "user_id="+userId, http.StatusBadRequest)
+		}
+
+		if !utils.StringInSlice(userId, memberIds) && !utils.StringInSlice(userId, newIds) {
+			newIds = append(newIds, userId)
+		}
+	}
+
+	if len(newIds) == 0 {
+		return channel, nil
+	}
+
+	if len(memberIds)+len(newIds) > model.CHANNEL_GROUP_MAX_USERS {
+		return nil, model.NewAppError("AddUsersToGroupChannel", "app.channel.group_members.too_many.app_error", map[string]interface{}{"Max": model.CHANNEL_GROUP_MAX_USERS}, "channel_id="+channel.Id, http.StatusBadRequest)
+	}
+
+	result := <-a.Srv.Store.User().GetProfileByIds(newIds, true)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	users := result.Data.([]*model.User)
+
+	if len(users) != len(newIds) {
+		return nil, model.NewAppError("AddUsersToGroupChannel", "app.channel.group_members.bad_user.app_error", nil, "user_ids="+model.ArrayToJson(newIds), http.StatusBadRequest)
+	}
+
+	for _, user := range users {
+		if user.DeleteAt > 0 {
+			return nil, model.NewAppError("AddUsersToGroupChannel", "app.channel.group_members.bad_user.app_error", nil, "user_id="+user.Id, http.StatusBadRequest)
+		}
+	}
+
+	allIds := append(append([]string{}, memberIds...), newIds...)
+	updated, err := a.renameGroupChannel(channel, allIds)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range users {
+		member := &model.ChannelMember{
+			ChannelId:   updated.Id,
+			UserId:      user.Id,
+			NotifyProps: model.GetDefaultChannelNotifyProps(),
+			Roles:       model.CHANNEL_USER_ROLE_ID,
+		}
+		if user.IsGuest() {
+			member.Roles = model.CHANNEL_GUEST_ROLE_ID
+		}
+
+		if result := <-a.Srv.Store.Channel().SaveMember(member); result.Err != nil {
+			return nil, result.Err
+		}
+
+		if result := <-a.Srv.Store.ChannelMemberHistory().LogJoinEvent(user.Id, updated.Id, model.GetMillis(), actorId); result.Err != nil {
+			mlog.Warn(fmt.Sprintf("Failed to update ChannelMemberHistory table %v", result.Err))
+		}
+
+		a.InvalidateCacheForUser(user.Id)
+	}
+	a.InvalidateCacheForChannelMembers(updated.Id)
+
+	// The new members are sent the same event as when a group channel is made so that it's added to their sidebars
+	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_GROUP_ADDED, "", updated.Id, "", nil)
+	message.Add("teammate_ids", model.ArrayToJson(allIds))
+	a.Publish(message)
+
+	actor, err := a.GetUser(actorId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range users {
+		message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_USER_ADDED, "", updated.Id, "", nil)
+		message.Add("user_id", user.Id)
+		message.Add("team_id", "")
+		a.Publish(message)
+
+		addedUser := user
+		a.Go(func() {
+			a.PostAddToChannelMessage(actor, addedUser, updated, "")
+		})
+	}
+
+	return updated, nil
+}
+
+// RemoveUsersFromGroupChannel removes the users from the group channel on behalf of actorId, renaming it like
+// AddUsersToGroupChannel does. Anyone can leave a group channel, but removing others is limited like adding them is.
+func (a *App) RemoveUsersFromGroupChannel(channel *model.Channel, userIds []string, actorId string) (*model.Channel, *model.AppError) {
+	if channel.Type != model.CHANNEL_GROUP {
+		return nil, model.NewAppError("RemoveUsersFromGroupChannel", "app.channel.group_members.type.app_error", nil, "channel_id="+channel.Id, http.StatusBadRequest)
+	}
+
+	for _, userId := range userIds {
+		if userId != actorId {
+			if err := a.CheckCanManageGroupChannelMembers(channel, actorId); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	memberIds, err := a.getGroupChannelMemberIds(channel)
+	if err != nil {
+		return nil, err
+	}
+
+	var remainingIds []string
+	for _, memberId := range memberIds {
+		if !utils.StringInSlice(memberId, userIds) {
+			remainingIds = append(remainingIds, memberId)
+		}
+	}
+
+	for _, userId := range userIds {
+		if !utils.StringInSlice(userId, memberIds) {
+			return nil, model.NewAppError("RemoveUsersFromGroupChannel", "app.channel.group_members.not_member.app_error", nil, "user_id="+userId, http.StatusBadRequest)
+		}
+	}
+
+	if len(userIds) == 0 {
+		return channel, nil
+	}
+
+	if len(remainingIds) == 0 {
+		return nil, model.NewAppError("RemoveUsersFromGroupChannel", "app.channel.group_members.empty.app_error", nil, "channel_id="+channel.Id, http.StatusBadRequest)
+	}
+
+	updated, err := a.renameGroupChannel(channel, remainingIds)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, userId := range userIds {
+		if err := a.RemoveUserFromChannel(userId, actorId, updated); err != nil {
+			return nil, err
+		}
+	}
+
+	return updated, nil
+}
+
+// GetRemovedGroupChannelMemberHistoryEnd returns when the user was last removed from or left the group channel if
+// TeamSettings.EnableGroupChannelRemovedMemberHistory lets them keep reading what was posted in it before then.
+// Otherwise, or if they never were a member, it returns 0.
+func (a *App) GetRemovedGroupChannelMemberHistoryEnd(channel *model.Channel, userId string) (int64, *model.AppError) {
+	if channel.Type != model.CHANNEL_GROUP || !*a.Config().TeamSettings.EnableGroupChannelRemovedMemberHistory {
+		return 0, nil
+	}
+
+	result := <-a.Srv.Store.ChannelMemberHistory().GetLastLeaveTime(userId, channel.Id)
+	if result.Err != nil {
+		return 0, result.Err
+	}
+
+	return result.Data.(int64), nil
+}
+
+func (a *App) getGroupChannelMemberIds(channel *model.Channel) ([]string, *model.AppError) {
+	// A few more than the maximum are fetched in case the channel has somehow ended up over it
+	result := <-a.Srv.Store.Channel().GetMembers(channel.Id, 0, model.CHANNEL_GROUP_MAX_USERS*2)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	var memberIds []string
+	for _, member := range *result.Data.(*model.ChannelMembers) {
+		memberIds = append(memberIds, member.UserId)
+	}
+
+	return memberIds, nil
+}
+
+// renameGroupChannel gives the group channel the name and display name that it would have if it had been made with
+// the given members.
+func (a *App) renameGroupChannel(channel *model.Channel, memberIds []string) (*model.Channel, *model.AppError) {
+	result := <-a.Srv.Store.User().GetProfileByIds(memberIds, true)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	users := result.Data.([]*model.User)
+
+	updated := channel.DeepCopy()
+	updated.Name = model.GetGroupNameFromUserIds(append([]string{}, memberIds...))
+	updated.DisplayName = model.GetGroupDisplayNameFromUsers(users, true)
+
+	renamed, err := a.UpdateChannel(updated)
+	if err != nil {
+		if err.Id == "store.sql_channel.update.exists.app_error" {
+			return nil, model.NewAppError("renameGroupChannel", "app.channel.group_members.exists.app_error", nil, "channel_id="+channel.Id, http.StatusBadRequest)
+		}
+		return nil, err
+	}
+
+	return renamed, nil
+}
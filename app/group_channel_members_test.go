@@ -0,0 +1,128 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestAddAndRemoveGroupChannelMembers(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	user1 := th.BasicUser
+	user2 := th.BasicUser2
+	user3 := th.CreateUser()
+
+	channel, err := th.App.CreateGroupChannel([]string{user1.Id, user2.Id, user3.Id}, user1.Id)
+	require.Nil(t, err)
+	assert.Equal(t, user1.Id, channel.CreatorId)
+
+	t.Run("adding renames the channel but keeps its id", func(t *testing.T) {
+		user4 := th.CreateUser()
+
+		updated, err := th.App.AddUsersToGroupChannel(channel, []string{user4.Id, user4.Id}, user2.Id)
+		require.Nil(t, err)
+		assert.Equal(t, channel.Id, updated.Id)
+		assert.Equal(t, model.GetGroupNameFromUserIds([]string{user1.Id, user2.Id, user3.Id, user4.Id}), updated.Name)
+		assert.Contains(t, updated.DisplayName, user4.Username)
+
+		_, err = th.App.GetChannelMember(channel.Id, user4.Id)
+		assert.Nil(t, err)
+
+		channel = updated
+	})
+
+	t.Run("members can't be added past the maximum", func(t *testing.T) {
+		var userIds []string
+		for i := 0; i < model.CHANNEL_GROUP_MAX_USERS-3; i++ {
+			userIds = append(userIds, th.CreateUser().Id)
+		}
+
+		_, err := th.App.AddUsersToGroupChannel(channel, userIds, user1.Id)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.channel.group_members.too_many.app_error", err.Id)
+	})
+
+	t.Run("removing renames the channel", func(t *testing.T) {
+		updated, err := th.App.RemoveUsersFromGroupChannel(channel, []string{user3.Id}, user1.Id)
+		require.Nil(t, err)
+		assert.Equal(t, channel.Id, updated.Id)
+		assert.NotContains(t, updated.DisplayName, user3.Username)
+
+		_, err = th.App.GetChannelMember(channel.Id, user3.Id)
+		assert.NotNil(t, err)
+
+		channel = updated
+	})
+
+	t.Run("only the creator can manage members when restricted to them", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) {
+			*cfg.TeamSettings.RestrictGroupChannelManageMembers = model.GROUP_CHANNEL_MANAGE_MEMBERS_CREATOR
+		})
+		defer th.App.UpdateConfig(func(cfg *model.Config) {
+			*cfg.TeamSettings.RestrictGroupChannelManageMembers = model.GROUP_CHANNEL_MANAGE_MEMBERS_ANY
+		})
+
+		_, err := th.App.AddUsersToGroupChannel(channel, []string{user3.Id}, user2.Id)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.channel.group_members.permission.app_error", err.Id)
+
+		// but anyone can still leave
+		updated, err := th.App.RemoveUsersFromGroupChannel(channel, []string{user2.Id}, user2.Id)
+		require.Nil(t, err)
+		channel = updated
+
+		updated, err = th.App.AddUsersToGroupChannel(channel, []string{user2.Id}, user1.Id)
+		require.Nil(t, err)
+		channel = updated
+	})
+
+	t.Run("non-members can't manage members", func(t *testing.T) {
+		_, err := th.App.AddUsersToGroupChannel(channel, []string{th.CreateUser().Id}, user3.Id)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.channel.group_members.permission.app_error", err.Id)
+	})
+
+	t.Run("removed members can read the history only if allowed", func(t *testing.T) {
+		updated, err := th.App.RemoveUsersFromGroupChannel(channel, []string{user2.Id}, user1.Id)
+		require.Nil(t, err)
+		channel = updated
+
+		historyEnd, err := th.App.GetRemovedGroupChannelMemberHistoryEnd(channel, user2.Id)
+		require.Nil(t, err)
+		assert.Equal(t, int64(0), historyEnd)
+
+		th.App.UpdateConfig(func(cfg *model.Config) {
+			*cfg.TeamSettings.EnableGroupChannelRemovedMemberHistory = true
+		})
+		defer th.App.UpdateConfig(func(cfg *model.Config) {
+			*cfg.TeamSettings.EnableGroupChannelRemovedMemberHistory = false
+		})
+
+		historyEnd, err = th.App.GetRemovedGroupChannelMemberHistoryEnd(channel, user2.Id)
+		require.Nil(t, err)
+		assert.NotEqual(t, int64(0), historyEnd)
+	})
+
+	t.Run("the last member can't be removed", func(t *testing.T) {
+		memberIds, err := th.App.getGroupChannelMemberIds(channel)
+		require.Nil(t, err)
+
+		_, err = th.App.RemoveUsersFromGroupChannel(channel, memberIds, user1.Id)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.channel.group_members.empty.app_error", err.Id)
+	})
+
+	t.Run("other channels can't be managed this way", func(t *testing.T) {
+		_, err := th.App.AddUsersToGroupChannel(th.BasicChannel, []string{user3.Id}, user1.Id)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.channel.group_members.type.app_error", err.Id)
+	})
+}
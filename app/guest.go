@@ -0,0 +1,132 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// PromoteGuestToUser turns a guest into a regular user, updating their team and channel roles to match.
+func (a *App) PromoteGuestToUser(user *model.User) (*model.User, *model.AppError) {
+	if !user.IsGuest() {
+		return nil, model.NewAppError("PromoteGuestToUser", "app.user.promote_guest.user_not_guest.app_error", nil, "user_id="+user.Id, http.StatusBadRequest)
+	}
+
+	if result := <-a.Srv.Store.User().PromoteGuestToUser(user.Id); result.Err != nil {
+		return nil, result.Err
+	}
+
+	return a.updateSessionsAfterGuestChange(user.Id)
+}
+
+// DemoteUserToGuest turns a regular user into a guest. Their existing team and channel memberships are
+// kept, but any admin roles they held are removed.
+func (a *App) DemoteUserToGuest(user *model.User) (*model.User, *model.AppError) {
+	if user.IsGuest() {
+		return nil, model.NewAppError("DemoteUserToGuest", "app.user.demote_user_to_guest.already_guest.app_error", nil, "user_id="+user.Id, http.StatusBadRequest)
+	}
+
+	if user.IsInRole(model.SYSTEM_ADMIN_ROLE_ID) {
+		return nil, model.NewAppError("DemoteUserToGuest", "app.user.demote_user_to_guest.system_admin.app_error", nil, "user_id="+user.Id, http.StatusBadRequest)
+	}
+
+	if result := <-a.Srv.Store.User().DemoteUserToGuest(user.Id); result.Err != nil {
+		return nil, result.Err
+	}
+
+	return a.updateSessionsAfterGuestChange(user.Id)
+}
+
+func (a *App) updateSessionsAfterGuestChange(userId string) (*model.User, *model.AppError) {
+	a.InvalidateCacheForUser(userId)
+
+	var user *model.User
+	if result := <-a.Srv.Store.User().Get(userId); result.Err != nil {
+		return nil, result.Err
+	} else {
+		user = result.Data.(*model.User)
+	}
+
+	if result := <-a.Srv.Store.Session().UpdateRoles(user.Id, user.Roles); result.Err != nil {
+		// soft error since the user roles were still updated
+		mlog.Error(fmt.Sprint(result.Err))
+	}
+
+	a.ClearSessionCacheForUser(user.Id)
+
+	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_USER_ROLE_UPDATED, "", "", user.Id, nil)
+	message.Add("user_id", user.Id)
+	message.Add("roles", user.Roles)
+	a.Publish(message)
+
+	return user, nil
+}
+
+// GetUsersInUserChannelsPage returns the users sharing at least one channel with the given user,
+// optionally limited to the channels of a single team.
+func (a *App) GetUsersInUserChannelsPage(userId string, teamId string, page int, perPage int, asAdmin bool) ([]*model.User, *model.AppError) {
+	if result := <-a.Srv.Store.User().GetProfilesInUserChannels(userId, teamId, page*perPage, perPage); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return a.sanitizeProfiles(result.Data.([]*model.User), asAdmin), nil
+	}
+}
+
+// SearchUsersInUserChannels searches the users sharing at least one channel with the given user.
+func (a *App) SearchUsersInUserChannels(userId string, teamId string, term string, searchOptions map[string]bool, asAdmin bool) ([]*model.User, *model.AppError) {
+	if result := <-a.Srv.Store.User().SearchInUserChannels(userId, teamId, term, searchOptions); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return a.sanitizeProfiles(result.Data.([]*model.User), asAdmin), nil
+	}
+}
+
+// RestrictUsersToUserChannels removes every user that doesn't share a channel with the given user.
+// The user themselves is always kept.
+func (a *App) RestrictUsersToUserChannels(userId string, users []*model.User) ([]*model.User, *model.AppError) {
+	userIds := make([]string, 0, len(users))
+	for _, user := range users {
+		if user.Id != userId {
+			userIds = append(userIds, user.Id)
+		}
+	}
+
+	result := <-a.Srv.Store.User().GetIdsSharingChannelsWithUser(userId, userIds)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	visible := map[string]bool{userId: true}
+	for _, id := range result.Data.([]string) {
+		visible[id] = true
+	}
+
+	restricted := []*model.User{}
+	for _, user := range users {
+		if visible[user.Id] {
+			restricted = append(restricted, user)
+		}
+	}
+
+	return restricted, nil
+}
+
+// SessionCanSeeUser returns true if the session's user is allowed to see another user. Everyone but
+// guests can see every user, while guests can only see the members of their channels.
+func (a *App) SessionCanSeeUser(session model.Session, userId string) (bool, *model.AppError) {
+	if !session.IsGuest() || session.UserId == userId {
+		return true, nil
+	}
+
+	result := <-a.Srv.Store.User().GetIdsSharingChannelsWithUser(session.UserId, []string{userId})
+	if result.Err != nil {
+		return false, result.Err
+	}
+
+	return len(result.Data.([]string)) > 0, nil
+}
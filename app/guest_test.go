@@ -0,0 +1,142 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func createGuestForTest(t *testing.T, th *TestHelper) *model.User {
+	id := model.NewId()
+	guest, err := th.App.CreateGuest(&model.User{
+		Email:         "success+" + id + "@simulator.amazonses.com",
+		Username:      "un_" + id,
+		Password:      "Password1",
+		EmailVerified: true,
+	})
+	require.Nil(t, err)
+	return guest
+}
+
+func TestCreateGuest(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	guest := createGuestForTest(t, th)
+	assert.True(t, guest.IsGuest())
+
+	th.LinkUserToTeam(guest, th.BasicTeam)
+	teamMember, err := th.App.GetTeamMember(th.BasicTeam.Id, guest.Id)
+	require.Nil(t, err)
+	assert.Equal(t, model.TEAM_GUEST_ROLE_ID, teamMember.Roles)
+
+	// Guests don't join the team's default channels
+	_, err = th.App.GetChannelMember(th.BasicChannel.Id, guest.Id)
+	assert.NotNil(t, err)
+
+	channelMember := th.AddUserToChannel(guest, th.BasicChannel)
+	assert.Equal(t, model.CHANNEL_GUEST_ROLE_ID, channelMember.Roles)
+}
+
+func TestPromoteAndDemoteGuest(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	guest := createGuestForTest(t, th)
+	th.LinkUserToTeam(guest, th.BasicTeam)
+	th.AddUserToChannel(guest, th.BasicChannel)
+
+	_, err := th.App.DemoteUserToGuest(guest)
+	assert.NotNil(t, err)
+
+	user, err := th.App.PromoteGuestToUser(guest)
+	require.Nil(t, err)
+	assert.False(t, user.IsGuest())
+
+	teamMember, err := th.App.GetTeamMember(th.BasicTeam.Id, user.Id)
+	require.Nil(t, err)
+	assert.Equal(t, model.TEAM_USER_ROLE_ID, teamMember.Roles)
+	channelMember, err := th.App.GetChannelMember(th.BasicChannel.Id, user.Id)
+	require.Nil(t, err)
+	assert.Equal(t, model.CHANNEL_USER_ROLE_ID, channelMember.Roles)
+
+	_, err = th.App.PromoteGuestToUser(user)
+	assert.NotNil(t, err)
+
+	user, err = th.App.DemoteUserToGuest(user)
+	require.Nil(t, err)
+	assert.True(t, user.IsGuest())
+
+	channelMember, err = th.App.GetChannelMember(th.BasicChannel.Id, user.Id)
+	require.Nil(t, err)
+	assert.Equal(t, model.CHANNEL_GUEST_ROLE_ID, channelMember.Roles)
+
+	admin := th.CreateUser()
+	admin, err = th.App.UpdateUserRoles(admin.Id, model.SYSTEM_USER_ROLE_ID+" "+model.SYSTEM_ADMIN_ROLE_ID, false)
+	require.Nil(t, err)
+	_, err = th.App.DemoteUserToGuest(admin)
+	assert.NotNil(t, err)
+}
+
+func TestRestrictUsersToUserChannels(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	guest := createGuestForTest(t, th)
+	th.LinkUserToTeam(guest, th.BasicTeam)
+	th.AddUserToChannel(guest, th.BasicChannel)
+
+	outsider := th.CreateUser()
+	th.LinkUserToTeam(outsider, th.BasicTeam)
+
+	users, err := th.App.RestrictUsersToUserChannels(guest.Id, []*model.User{guest, th.BasicUser, outsider})
+	require.Nil(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, guest.Id, users[0].Id)
+	assert.Equal(t, th.BasicUser.Id, users[1].Id)
+
+	canSee, err := th.App.SessionCanSeeUser(model.Session{UserId: guest.Id, Roles: guest.Roles}, outsider.Id)
+	require.Nil(t, err)
+	assert.False(t, canSee)
+
+	canSee, err = th.App.SessionCanSeeUser(model.Session{UserId: guest.Id, Roles: guest.Roles}, th.BasicUser.Id)
+	require.Nil(t, err)
+	assert.True(t, canSee)
+
+	canSee, err = th.App.SessionCanSeeUser(model.Session{UserId: th.BasicUser.Id, Roles: th.BasicUser.Roles}, outsider.Id)
+	require.Nil(t, err)
+	assert.True(t, canSee)
+}
+
+func TestInviteGuestsToChannels(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	invite := &model.GuestsInvite{
+		Emails:   []string{"success+" + model.NewId() + "@simulator.amazonses.com"},
+		Channels: []string{th.BasicChannel.Id},
+	}
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.GuestAccountsSettings.Enable = false })
+	err := th.App.InviteGuestsToChannels(th.BasicTeam.Id, invite, th.BasicUser.Id)
+	require.NotNil(t, err)
+	assert.Equal(t, "api.team.invite_guests.disabled.app_error", err.Id)
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.GuestAccountsSettings.Enable = true })
+
+	otherTeam := th.CreateTeam()
+	otherChannel := th.CreateChannel(otherTeam)
+	invite.Channels = []string{otherChannel.Id}
+	err = th.App.InviteGuestsToChannels(th.BasicTeam.Id, invite, th.BasicUser.Id)
+	require.NotNil(t, err)
+	assert.Equal(t, "api.team.invite_guests.channel.app_error", err.Id)
+
+	invite.Channels = []string{th.BasicChannel.Id}
+	assert.Nil(t, th.App.InviteGuestsToChannels(th.BasicTeam.Id, invite, th.BasicUser.Id))
+}
@@ -0,0 +1,183 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const IDLE_DEACTIVATION_BATCH_SIZE = 1000
+
+// getIdleUsers returns the active users that haven't been seen since idleBefore, including those that have
+// never been seen and were created before then.
+func (a *App) getIdleUsers(idleBefore int64) ([]*model.User, *model.AppError) {
+	active := true
+	filter := &model.UserFilter{
+		Active:         &active,
+		CreatedBefore:  idleBefore,
+		LastSeenBefore: idleBefore,
+	}
+
+	var users []*model.User
+	for offset := 0; ; offset += IDLE_DEACTIVATION_BATCH_SIZE {
+		result := <-a.Srv.Store.User().GetProfilesWithFilter(filter, offset, IDLE_DEACTIVATION_BATCH_SIZE)
+		if result.Err != nil {
+			return nil, result.Err
+		}
+
+		batch := result.Data.([]*model.User)
+		users = append(users, batch...)
+
+		if len(batch) < IDLE_DEACTIVATION_BATCH_SIZE {
+			return users, nil
+		}
+	}
+}
+
+func (a *App) isExemptFromIdleDeactivation(user *model.User, settings model.DeactivationSettings) bool {
+	if user.IsBot && *settings.ExemptBots {
+		return true
+	}
+
+	for _, role := range strings.Fields(*settings.ExemptRoles) {
+		if user.IsInRole(role) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lastSeenInSessions returns the last activity of any of the user's sessions. Users that haven't signed in
+// since LastSeenAt started being recorded may still have been active in a session that was already open.
+func (a *App) lastSeenInSessions(userId string) (int64, *model.AppError) {
+	result := <-a.Srv.Store.Session().GetSessions(userId)
+	if result.Err != nil {
+		return 0, result.Err
+	}
+
+	var lastSeenAt int64
+	for _, session := range result.Data.([]*model.Session) {
+		if session.LastActivityAt > lastSeenAt && !session.IsImpersonation {
+			lastSeenAt = session.LastActivityAt
+		}
+	}
+
+	return lastSeenAt, nil
+}
+
+// getIdleDeactivationWarning returns when the user was warned that they're about to be deactivated, or 0 if
+// they haven't been warned since they were last seen.
+func (a *App) getIdleDeactivationWarning(user *model.User) (int64, *model.AppError) {
+	result := <-a.Srv.Store.Preference().GetCategory(user.Id, model.PREFERENCE_CATEGORY_IDLE_DEACTIVATION)
+	if result.Err != nil {
+		return 0, result.Err
+	}
+
+	for _, preference := range result.Data.(model.Preferences) {
+		if preference.Name != model.PREFERENCE_NAME_IDLE_WARNED_AT {
+			continue
+		}
+
+		if warnedAt, err := strconv.ParseInt(preference.Value, 10, 64); err == nil && warnedAt > user.LastSeenAt {
+			return warnedAt, nil
+		}
+	}
+
+	return 0, nil
+}
+
+func (a *App) setIdleDeactivationWarning(userId string, warnedAt int64) *model.AppError {
+	preference := model.Preference{
+		UserId:   userId,
+		Category: model.PREFERENCE_CATEGORY_IDLE_DEACTIVATION,
+		Name:     model.PREFERENCE_NAME_IDLE_WARNED_AT,
+		Value:    strconv.FormatInt(warnedAt, 10),
+	}
+
+	if result := <-a.Srv.Store.Preference().Save(&model.Preferences{preference}); result.Err != nil {
+		return result.Err
+	}
+
+	return nil
+}
+
+// DeactivateIdleUsers finds the users that haven't been seen for the configured number of days. Users that
+// haven't been warned yet are sent an email saying that they'll be deactivated once the grace period is over,
+// and users that were warned at least that long ago are deactivated, which also revokes their sessions. System
+// admins and bots are exempt unless configured otherwise. If dryRun is set, nothing is changed and the result
+// holds what would have been.
+func (a *App) DeactivateIdleUsers(now int64, dryRun bool) (*model.IdleDeactivationResult, *model.AppError) {
+	settings := a.Config().DeactivationSettings
+	day := int64(24 * time.Hour / time.Millisecond)
+	gracePeriod := int64(*settings.GracePeriodDays) * day
+
+	result := &model.IdleDeactivationResult{
+		DryRun:             dryRun,
+		IdleBefore:         now - int64(*settings.IdleDays)*day,
+		WarnedUserIds:      []string{},
+		DeactivatedUserIds: []string{},
+	}
+
+	users, err := a.getIdleUsers(result.IdleBefore)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range users {
+		if a.isExemptFromIdleDeactivation(user, settings) {
+			result.ExemptCount++
+			continue
+		}
+
+		if lastSeenAt, err := a.lastSeenInSessions(user.Id); err != nil {
+			return nil, err
+		} else if lastSeenAt >= result.IdleBefore {
+			if !dryRun {
+				<-a.Srv.Store.User().UpdateLastSeenAt(user.Id, lastSeenAt)
+			}
+			continue
+		}
+
+		warnedAt, err := a.getIdleDeactivationWarning(user)
+		if err != nil {
+			return nil, err
+		}
+
+		if warnedAt == 0 {
+			if !dryRun {
+				deactivateAt := time.Unix(0, (now+gracePeriod)*int64(time.Millisecond)).In(a.GetUserTimezoneLocation(user))
+				if err := a.SendIdleDeactivationWarningEmail(user.Email, *settings.IdleDays, deactivateAt, user.Locale, a.GetSiteURL()); err != nil {
+					// Don't start the grace period until the user has been told about it
+					mlog.Error("Unable to warn idle user about their deactivation", mlog.String("user_id", user.Id), mlog.Err(err))
+					continue
+				}
+
+				if err := a.setIdleDeactivationWarning(user.Id, now); err != nil {
+					return nil, err
+				}
+			}
+
+			result.WarnedUserIds = append(result.WarnedUserIds, user.Id)
+		} else if warnedAt+gracePeriod <= now {
+			if !dryRun {
+				if _, err := a.UpdateActive(user, false); err != nil {
+					mlog.Error("Unable to deactivate idle user", mlog.String("user_id", user.Id), mlog.Err(err))
+					continue
+				}
+
+				<-a.Srv.Store.Preference().Delete(user.Id, model.PREFERENCE_CATEGORY_IDLE_DEACTIVATION, model.PREFERENCE_NAME_IDLE_WARNED_AT)
+			}
+
+			result.DeactivatedUserIds = append(result.DeactivatedUserIds, user.Id)
+		}
+	}
+
+	return result, nil
+}
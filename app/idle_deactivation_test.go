@@ -0,0 +1,150 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+func TestDeactivateIdleUsers(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.DeactivationSettings.IdleDays = 30
+		*cfg.DeactivationSettings.GracePeriodDays = 7
+	})
+
+	day := int64(24 * 60 * 60 * 1000)
+	now := model.GetMillis() + 31*day
+
+	idle := th.CreateUser()
+	session, err := th.App.CreateSession(&model.Session{UserId: idle.Id})
+	require.Nil(t, err)
+	utils.DeleteMailBox(idle.Email)
+
+	admin := th.CreateUser()
+	_, err = th.App.UpdateUserRoles(admin.Id, model.SYSTEM_USER_ROLE_ID+" "+model.SYSTEM_ADMIN_ROLE_ID, false)
+	require.Nil(t, err)
+
+	bot, err := th.App.EnsureSystemBot()
+	require.Nil(t, err)
+
+	// A user that's still active in a session they signed in to before their last seen time was recorded
+	active := th.CreateUser()
+	activeSession, err := th.App.CreateSession(&model.Session{UserId: active.Id})
+	require.Nil(t, err)
+	require.Nil(t, (<-th.App.Srv.Store.Session().UpdateLastActivityAt(activeSession.Id, now-day)).Err)
+
+	result, err := th.App.DeactivateIdleUsers(now, true)
+	require.Nil(t, err)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, now-30*day, result.IdleBefore)
+	assert.Contains(t, result.WarnedUserIds, idle.Id)
+	assert.NotContains(t, result.WarnedUserIds, admin.Id)
+	assert.NotContains(t, result.WarnedUserIds, bot.Id)
+	assert.NotContains(t, result.WarnedUserIds, active.Id)
+
+	warnedAt, err := th.App.getIdleDeactivationWarning(idle)
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), warnedAt, "a dry run shouldn't warn anyone")
+
+	result, err = th.App.DeactivateIdleUsers(now, false)
+	require.Nil(t, err)
+	assert.False(t, result.DryRun)
+	assert.Contains(t, result.WarnedUserIds, idle.Id)
+	assert.NotContains(t, result.WarnedUserIds, active.Id)
+	assert.NotContains(t, result.DeactivatedUserIds, idle.Id)
+
+	warnedAt, err = th.App.getIdleDeactivationWarning(idle)
+	require.Nil(t, err)
+	assert.Equal(t, now, warnedAt)
+
+	ruser, err := th.App.GetUser(active.Id)
+	require.Nil(t, err)
+	assert.Equal(t, now-day, ruser.LastSeenAt, "should've recorded the session's activity")
+
+	var resultsMailbox utils.JSONMessageHeaderInbucket
+	mailErr := utils.RetryInbucket(5, func() error {
+		var err error
+		resultsMailbox, err = utils.GetMailBox(idle.Email)
+		return err
+	})
+	if mailErr != nil {
+		t.Log(mailErr)
+		t.Log("No email was received, maybe due load on the server. Disabling this verification")
+	} else if len(resultsMailbox) > 0 {
+		assert.Contains(t, resultsMailbox[0].To[0], idle.Email)
+		assert.Contains(t, resultsMailbox[0].Subject, "Your account will be deactivated")
+	}
+
+	// Nothing happens until the grace period is over
+	result, err = th.App.DeactivateIdleUsers(now+6*day, false)
+	require.Nil(t, err)
+	assert.NotContains(t, result.WarnedUserIds, idle.Id)
+	assert.NotContains(t, result.DeactivatedUserIds, idle.Id)
+
+	result, err = th.App.DeactivateIdleUsers(now+7*day, false)
+	require.Nil(t, err)
+	assert.Contains(t, result.DeactivatedUserIds, idle.Id)
+	assert.NotContains(t, result.DeactivatedUserIds, admin.Id)
+	assert.NotContains(t, result.DeactivatedUserIds, bot.Id)
+
+	ruser, err = th.App.GetUser(idle.Id)
+	require.Nil(t, err)
+	assert.NotEqual(t, int64(0), ruser.DeleteAt)
+
+	_, err = th.App.GetSession(session.Token)
+	assert.NotNil(t, err, "should've revoked the user's sessions")
+
+	ruser, err = th.App.GetUser(admin.Id)
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), ruser.DeleteAt)
+
+	t.Run("signing in after the warning restarts the idle period", func(t *testing.T) {
+		user := th.CreateUser()
+
+		result, err := th.App.DeactivateIdleUsers(now, false)
+		require.Nil(t, err)
+		require.Contains(t, result.WarnedUserIds, user.Id)
+
+		require.Nil(t, (<-th.App.Srv.Store.User().UpdateLastSeenAt(user.Id, now+day)).Err)
+
+		result, err = th.App.DeactivateIdleUsers(now+7*day, false)
+		require.Nil(t, err)
+		assert.NotContains(t, result.DeactivatedUserIds, user.Id)
+
+		// Once they're idle again they're warned again instead of being deactivated straight away
+		result, err = th.App.DeactivateIdleUsers(now+32*day, false)
+		require.Nil(t, err)
+		assert.Contains(t, result.WarnedUserIds, user.Id)
+		assert.NotContains(t, result.DeactivatedUserIds, user.Id)
+
+		warnedAt, err := th.App.getIdleDeactivationWarning(&model.User{Id: user.Id, LastSeenAt: now + day})
+		require.Nil(t, err)
+		assert.Equal(t, now+32*day, warnedAt)
+	})
+
+	t.Run("exempt roles and bots are configurable", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) {
+			*cfg.DeactivationSettings.ExemptRoles = ""
+			*cfg.DeactivationSettings.ExemptBots = false
+		})
+		defer th.App.UpdateConfig(func(cfg *model.Config) {
+			*cfg.DeactivationSettings.ExemptRoles = model.DEACTIVATION_SETTINGS_DEFAULT_EXEMPT_ROLES
+			*cfg.DeactivationSettings.ExemptBots = true
+		})
+
+		result, err := th.App.DeactivateIdleUsers(now, true)
+		require.Nil(t, err)
+		assert.Contains(t, result.WarnedUserIds, admin.Id)
+		assert.Contains(t, result.WarnedUserIds, bot.Id)
+	})
+}
@@ -0,0 +1,199 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const (
+	IMAGE_PROXY_CACHE_DIRECTORY   = "image_proxy"
+	IMAGE_PROXY_CACHE_EXPIRY_SECS = 24 * 60 * 60
+	IMAGE_PROXY_FETCH_TIMEOUT     = 30 * time.Second
+)
+
+var imageProxyAllowedContentTypes = map[string]bool{
+	"image/bmp":                true,
+	"image/gif":                true,
+	"image/jpeg":               true,
+	"image/png":                true,
+	"image/webp":               true,
+	"image/x-icon":             true,
+	"image/vnd.microsoft.icon": true,
+}
+
+type imageProxyCacheEntry struct {
+	ContentType string `json:"content_type"`
+	ExpireAt    int64  `json:"expire_at"`
+}
+
+// ImageProxySignature returns the signature that must accompany an image URL for the local image
+// proxy to serve it. This prevents the proxy from being used to fetch arbitrary URLs. It fails if the
+// server has no signing key, since anyone could make a signature without one.
+func (a *App) ImageProxySignature(imageURL string) (string, *model.AppError) {
+	var key []byte
+	if signingKey := a.AsymmetricSigningKey(); signingKey != nil && signingKey.D != nil {
+		key = signingKey.D.Bytes()
+	}
+	if len(key) == 0 {
+		return "", model.NewAppError("ImageProxySignature", "app.image_proxy.signing_key.app_error", nil, "", http.StatusInternalServerError)
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(imageURL))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// VerifyImageProxySignature returns an error unless signature is the one for imageURL.
+func (a *App) VerifyImageProxySignature(imageURL, signature string) *model.AppError {
+	expected, err := a.ImageProxySignature(imageURL)
+	if err != nil {
+		return err
+	}
+
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return model.NewAppError("VerifyImageProxySignature", "api.image_proxy.signature.app_error", nil, "", http.StatusForbidden)
+	}
+
+	return nil
+}
+
+// localImageProxyURL returns the URL of imageURL through the local image proxy. It's left unsigned if it
+// can't be signed, so the proxy refuses to serve it rather than the image being fetched from elsewhere.
+func (a *App) localImageProxyURL(proxyURL, imageURL string) string {
+	proxied := proxyURL + "?url=" + url.QueryEscape(imageURL)
+
+	signature, err := a.ImageProxySignature(imageURL)
+	if err != nil {
+		return proxied
+	}
+
+	return proxied + "&sig=" + signature
+}
+
+// localImageProxyTarget returns the original image URL from a URL generated by localImageProxyURL, or
+// an empty string if proxiedURL doesn't point at the local image proxy.
+func localImageProxyTarget(proxyURL, proxiedURL string) string {
+	if !strings.HasPrefix(proxiedURL, proxyURL+"?") {
+		return ""
+	}
+
+	query, err := url.ParseQuery(proxiedURL[len(proxyURL)+1:])
+	if err != nil {
+		return ""
+	}
+
+	return query.Get("url")
+}
+
+// GetProxiedImage returns the image at the given URL and its content type, fetching it from the
+// remote server if a copy isn't already cached in the file store.
+func (a *App) GetProxiedImage(imageURL string) ([]byte, string, *model.AppError) {
+	if parsed, err := url.Parse(imageURL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil, "", model.NewAppError("GetProxiedImage", "api.image_proxy.invalid_url.app_error", nil, "url="+imageURL, http.StatusBadRequest)
+	}
+
+	cachePath := imageProxyCachePath(imageURL)
+
+	if data, contentType, ok := a.getCachedProxiedImage(cachePath); ok {
+		return data, contentType, nil
+	}
+
+	data, contentType, err := a.fetchProxiedImage(imageURL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	entry := &imageProxyCacheEntry{
+		ContentType: contentType,
+		ExpireAt:    model.GetMillis() + IMAGE_PROXY_CACHE_EXPIRY_SECS*1000,
+	}
+	entryJSON, _ := json.Marshal(entry)
+
+	if _, err := a.WriteFile(bytes.NewReader(data), cachePath); err != nil {
+		mlog.Warn(fmt.Sprintf("Unable to cache proxied image url=%v err=%v", imageURL, err.Error()))
+	} else if _, err := a.WriteFile(bytes.NewReader(entryJSON), cachePath+".json"); err != nil {
+		mlog.Warn(fmt.Sprintf("Unable to cache proxied image url=%v err=%v", imageURL, err.Error()))
+	}
+
+	return data, contentType, nil
+}
+
+func imageProxyCachePath(imageURL string) string {
+	hash := sha256.Sum256([]byte(imageURL))
+	return IMAGE_PROXY_CACHE_DIRECTORY + "/" + hex.EncodeToString(hash[:])
+}
+
+func (a *App) getCachedProxiedImage(cachePath string) ([]byte, string, bool) {
+	entryJSON, err := a.ReadFile(cachePath + ".json")
+	if err != nil {
+		return nil, "", false
+	}
+
+	var entry imageProxyCacheEntry
+	if jsonErr := json.Unmarshal(entryJSON, &entry); jsonErr != nil || entry.ExpireAt < model.GetMillis() {
+		a.RemoveFile(cachePath)
+		a.RemoveFile(cachePath + ".json")
+		return nil, "", false
+	}
+
+	data, err := a.ReadFile(cachePath)
+	if err != nil {
+		return nil, "", false
+	}
+
+	return data, entry.ContentType, true
+}
+
+func (a *App) fetchProxiedImage(imageURL string) ([]byte, string, *model.AppError) {
+	client := a.HTTPClient(false)
+	client.Timeout = IMAGE_PROXY_FETCH_TIMEOUT
+	client.CheckRedirect = checkUntrustedRedirect
+
+	res, err := client.Get(imageURL)
+	if err != nil {
+		return nil, "", model.NewAppError("GetProxiedImage", "api.image_proxy.fetch.app_error", nil, "url="+imageURL+" err="+err.Error(), http.StatusBadGateway)
+	}
+	defer consumeAndClose(res)
+
+	if res.StatusCode != http.StatusOK {
+		return nil, "", model.NewAppError("GetProxiedImage", "api.image_proxy.fetch.app_error", nil, fmt.Sprintf("url=%v status=%v", imageURL, res.StatusCode), http.StatusBadGateway)
+	}
+
+	maxSize := *a.Config().FileSettings.MaxFileSize
+	if res.ContentLength > maxSize {
+		return nil, "", model.NewAppError("GetProxiedImage", "api.image_proxy.too_large.app_error", nil, "url="+imageURL, http.StatusBadGateway)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(res.Body, maxSize+1))
+	if err != nil {
+		return nil, "", model.NewAppError("GetProxiedImage", "api.image_proxy.fetch.app_error", nil, "url="+imageURL+" err="+err.Error(), http.StatusBadGateway)
+	} else if int64(len(data)) > maxSize {
+		return nil, "", model.NewAppError("GetProxiedImage", "api.image_proxy.too_large.app_error", nil, "url="+imageURL, http.StatusBadGateway)
+	}
+
+	// Check both the declared and the detected content types so that a remote server can't get the
+	// proxy to serve something other than an image.
+	contentType := strings.ToLower(strings.TrimSpace(strings.Split(res.Header.Get("Content-Type"), ";")[0]))
+	detected := strings.Split(http.DetectContentType(data), ";")[0]
+	if !imageProxyAllowedContentTypes[contentType] || !imageProxyAllowedContentTypes[detected] {
+		return nil, "", model.NewAppError("GetProxiedImage", "api.image_proxy.content_type.app_error", nil, fmt.Sprintf("url=%v content_type=%v detected=%v", imageURL, contentType, detected), http.StatusBadGateway)
+	}
+
+	return data, contentType, nil
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func testProxiedImage(t *testing.T) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.Set(0, 0, color.White)
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestLocalImageProxyURLs(t *testing.T) {
+	th := Setup()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.ServiceSettings.SiteURL = "http://mymattermost.com"
+		*cfg.ServiceSettings.ImageProxyType = model.IMAGE_PROXY_TYPE_LOCAL
+	})
+
+	imageURL := "http://mydomain.com/myimage?size=large"
+	proxied := th.App.ImageProxyAdder()(imageURL)
+
+	parsed, err := url.Parse(proxied)
+	require.NoError(t, err)
+	assert.Equal(t, "mymattermost.com", parsed.Host)
+	assert.Equal(t, model.API_URL_SUFFIX+"/image", parsed.Path)
+	assert.Equal(t, imageURL, parsed.Query().Get("url"))
+
+	assert.Nil(t, th.App.VerifyImageProxySignature(imageURL, parsed.Query().Get("sig")))
+	assert.NotNil(t, th.App.VerifyImageProxySignature("http://mydomain.com/otherimage", parsed.Query().Get("sig")))
+	assert.NotNil(t, th.App.VerifyImageProxySignature(imageURL, ""))
+
+	assert.Equal(t, proxied, th.App.ImageProxyAdder()(proxied), "already proxied URLs should not be proxied again")
+	assert.Equal(t, imageURL, th.App.ImageProxyRemover()(proxied))
+	assert.Equal(t, "http://mymattermost.com/myimage", th.App.ImageProxyAdder()("http://mymattermost.com/myimage"))
+
+	t.Run("no signing key", func(t *testing.T) {
+		signingKey := th.App.asymmetricSigningKey
+		th.App.asymmetricSigningKey = nil
+		defer func() { th.App.asymmetricSigningKey = signingKey }()
+
+		_, err := th.App.ImageProxySignature(imageURL)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.image_proxy.signing_key.app_error", err.Id)
+
+		// An image can't be proxied with the signature made from an empty key
+		mac := hmac.New(sha256.New, nil)
+		mac.Write([]byte(imageURL))
+		assert.NotNil(t, th.App.VerifyImageProxySignature(imageURL, hex.EncodeToString(mac.Sum(nil))))
+
+		parsed, parseErr := url.Parse(th.App.ImageProxyAdder()(imageURL))
+		require.NoError(t, parseErr)
+		assert.Equal(t, imageURL, parsed.Query().Get("url"))
+		assert.Equal(t, "", parsed.Query().Get("sig"))
+	})
+}
+
+func TestGetProxiedImage(t *testing.T) {
+	th := Setup()
+	defer th.TearDown()
+
+	imageData := testProxiedImage(t)
+
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(imageData)
+		case "/not-an-image.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("<html><script>alert('hi')</script></html>"))
+		}
+	}))
+	defer ts.Close()
+
+	t.Run("blocked internal target", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) {
+			*cfg.ServiceSettings.AllowedUntrustedInternalConnections = ""
+		})
+
+		_, _, err := th.App.GetProxiedImage(ts.URL + "/image.png")
+		require.NotNil(t, err)
+		assert.Equal(t, "api.image_proxy.fetch.app_error", err.Id)
+		assert.Equal(t, 0, requests)
+	})
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.ServiceSettings.AllowedUntrustedInternalConnections = "localhost 127.0.0.1"
+	})
+
+	t.Run("invalid scheme", func(t *testing.T) {
+		_, _, err := th.App.GetProxiedImage("file:///etc/passwd")
+		require.NotNil(t, err)
+		assert.Equal(t, "api.image_proxy.invalid_url.app_error", err.Id)
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		requests = 0
+		imageURL := ts.URL + "/image.png"
+		defer th.App.RemoveFile(imageProxyCachePath(imageURL))
+		defer th.App.RemoveFile(imageProxyCachePath(imageURL) + ".json")
+
+		data, contentType, err := th.App.GetProxiedImage(imageURL)
+		require.Nil(t, err)
+		assert.Equal(t, imageData, data)
+		assert.Equal(t, "image/png", contentType)
+
+		data, _, err = th.App.GetProxiedImage(imageURL)
+		require.Nil(t, err)
+		assert.Equal(t, imageData, data)
+		assert.Equal(t, 1, requests, "second request should have been served from the cache")
+	})
+
+	t.Run("content type mismatch", func(t *testing.T) {
+		_, _, err := th.App.GetProxiedImage(ts.URL + "/not-an-image.png")
+		require.NotNil(t, err)
+		assert.Equal(t, "api.image_proxy.content_type.app_error", err.Id)
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) {
+			*cfg.FileSettings.MaxFileSize = 10
+		})
+
+		_, _, err := th.App.GetProxiedImage(ts.URL + "/image.png?large")
+		require.NotNil(t, err)
+		assert.Equal(t, "api.image_proxy.too_large.app_error", err.Id)
+	})
+}
@@ -810,7 +810,7 @@ func (a *App) ImportUserTeams(user *model.User, data *[]UserTeamImportData) *mod
 
 		if defaultChannel, err := a.GetChannelByName(model.DEFAULT_CHANNEL, team.Id); err != nil {
 			return err
-		} else if _, err = a.addUserToChannel(user, defaultChannel, member); err != nil {
+		} else if _, err = a.addUserToChannel(user, defaultChannel, member, model.CHANNEL_MEMBER_HISTORY_ACTOR_SYSTEM); err != nil {
 			return err
 		}
 
@@ -846,7 +846,7 @@ func (a *App) ImportUserChannels(user *model.User, team *model.Team, teamMember
 		var member *model.ChannelMember
 		member, err = a.GetChannelMember(channel.Id, user.Id)
 		if err != nil {
-			member, err = a.addUserToChannel(user, channel, teamMember)
+			member, err = a.addUserToChannel(user, channel, teamMember, model.CHANNEL_MEMBER_HISTORY_ACTOR_SYSTEM)
 			if err != nil {
 				return err
 			}
@@ -1265,6 +1265,8 @@ func validateReplyImportData(data *ReplyImportData, parentCreateAt int64, maxPos
 
 	if data.Message == nil {
 		return model.NewAppError("BulkImport", "app.import.validate_reply_import_data.message_missing.error", nil, "", http.StatusBadRequest)
+	} else if strings.TrimSpace(*data.Message) == "" {
+		return model.NewAppError("BulkImport", "app.import.validate_reply_import_data.message_empty.error", nil, "", http.StatusBadRequest)
 	} else if utf8.RuneCountInString(*data.Message) > maxPostSize {
 		return model.NewAppError("BulkImport", "app.import.validate_reply_import_data.message_length.error", nil, "", http.StatusBadRequest)
 	}
@@ -1295,6 +1297,8 @@ func validatePostImportData(data *PostImportData, maxPostSize int) *model.AppErr
 
 	if data.Message == nil {
 		return model.NewAppError("BulkImport", "app.import.validate_post_import_data.message_missing.error", nil, "", http.StatusBadRequest)
+	} else if strings.TrimSpace(*data.Message) == "" {
+		return model.NewAppError("BulkImport", "app.import.validate_post_import_data.message_empty.error", nil, "", http.StatusBadRequest)
 	} else if utf8.RuneCountInString(*data.Message) > maxPostSize {
 		return model.NewAppError("BulkImport", "app.import.validate_post_import_data.message_length.error", nil, "", http.StatusBadRequest)
 	}
@@ -1578,6 +1582,8 @@ func validateDirectPostImportData(data *DirectPostImportData, maxPostSize int) *
 
 	if data.Message == nil {
 		return model.NewAppError("BulkImport", "app.import.validate_direct_post_import_data.message_missing.error", nil, "", http.StatusBadRequest)
+	} else if strings.TrimSpace(*data.Message) == "" {
+		return model.NewAppError("BulkImport", "app.import.validate_direct_post_import_data.message_empty.error", nil, "", http.StatusBadRequest)
 	} else if utf8.RuneCountInString(*data.Message) > maxPostSize {
 		return model.NewAppError("BulkImport", "app.import.validate_direct_post_import_data.message_length.error", nil, "", http.StatusBadRequest)
 	}
@@ -1624,7 +1630,17 @@ func validateDirectPostImportData(data *DirectPostImportData, maxPostSize int) *
 // some of the usual checks. (IsValid is still run)
 //
 
-func (a *App) OldImportPost(post *model.Post) {
+// OldImportPost saves the post, splitting it into several if its message is too long, and returns the id of the first
+// one that was saved.
+func (a *App) OldImportPost(post *model.Post) string {
+	var firstPostId string
+
+	// Files past the limit are left out rather than failing the rest of the import
+	if max := *a.Config().FileSettings.MaxFileAttachments; len(post.FileIds) > max {
+		mlog.Warn(fmt.Sprintf("Only attaching the first %v files to an imported post. user=%v, file_ids=%v", max, post.UserId, post.FileIds))
+		post.FileIds = post.FileIds[:max]
+	}
+
 	// Workaround for empty messages, which may be the case if they are webhook posts.
 	firstIteration := true
 	maxPostSize := a.MaxPostSize()
@@ -1642,6 +1658,8 @@ func (a *App) OldImportPost(post *model.Post) {
 
 		if result := <-a.Srv.Store.Post().Save(post); result.Err != nil {
 			mlog.Debug(fmt.Sprintf("Error saving post. user=%v, message=%v", post.UserId, post.Message))
+		} else if firstPostId == "" {
+			firstPostId = post.Id
 		}
 
 		for _, fileId := range post.FileIds {
@@ -1654,6 +1672,8 @@ func (a *App) OldImportPost(post *model.Post) {
 		post.CreateAt++
 		post.Message = remainder
 	}
+
+	return firstPostId
 }
 
 func (a *App) OldImportUser(team *model.Team, user *model.User) *model.User {
@@ -1708,7 +1728,7 @@ func (a *App) OldImportFile(timestamp time.Time, file io.Reader, teamId string,
 	return fileInfo, nil
 }
 
-func (a *App) OldImportIncomingWebhookPost(post *model.Post, props model.StringInterface) {
+func (a *App) OldImportIncomingWebhookPost(post *model.Post, props model.StringInterface) string {
 	linkWithTextRegex := regexp.MustCompile(`<([^<\|]+)\|([^>]+)>`)
 	post.Message = linkWithTextRegex.ReplaceAllString(post.Message, "[${2}](${1})")
 
@@ -1730,5 +1750,5 @@ func (a *App) OldImportIncomingWebhookPost(post *model.Post, props model.StringI
 		}
 	}
 
-	a.OldImportPost(post)
+	return a.OldImportPost(post)
 }
@@ -174,7 +174,7 @@ func TestImportValidateTeamImportData(t *testing.T) {
 		t.Fatal("Should have succeeded with allow open invites false.")
 	}
 
-	data.Description = ptrStr(strings.Repeat("abcdefghij ", 26))
+	data.Description = ptrStr(strings.Repeat("abcdefghij ", 94))
 	if err := validateTeamImportData(&data); err == nil {
 		t.Fatal("Should have failed due to too long description.")
 	}
@@ -281,13 +281,13 @@ func TestImportValidateChannelImportData(t *testing.T) {
 		t.Fatal("Should have succeeded with valid optional properties.")
 	}
 
-	data.Header = ptrStr(strings.Repeat("abcdefghij ", 103))
+	data.Header = ptrStr(strings.Repeat("abcdefghij ", 373))
 	if err := validateChannelImportData(&data); err == nil {
 		t.Fatal("Should have failed due to too long header.")
 	}
 
 	data.Header = ptrStr("Channel Header Here")
-	data.Purpose = ptrStr(strings.Repeat("abcdefghij ", 26))
+	data.Purpose = ptrStr(strings.Repeat("abcdefghij ", 94))
 	if err := validateChannelImportData(&data); err == nil {
 		t.Fatal("Should have failed due to too long purpose.")
 	}
@@ -669,6 +669,11 @@ func TestImportValidateReplyImportData(t *testing.T) {
 		t.Fatal("Should have failed due to too long message.")
 	}
 
+	data.Message = ptrStr("  ")
+	if err := validateReplyImportData(&data, parentCreateAt, maxPostSize); err == nil || err.Id != "app.import.validate_reply_import_data.message_empty.error" {
+		t.Fatal("Should have failed due to empty message.")
+	}
+
 	// Test with invalid CreateAt
 	data = ReplyImportData{
 		User:     ptrStr("username"),
@@ -767,6 +772,11 @@ func TestImportValidatePostImportData(t *testing.T) {
 		t.Fatal("Should have failed due to too long message.")
 	}
 
+	data.Message = ptrStr("")
+	if err := validatePostImportData(&data, maxPostSize); err == nil || err.Id != "app.import.validate_post_import_data.message_empty.error" {
+		t.Fatal("Should have failed due to empty message.")
+	}
+
 	// Test with invalid CreateAt
 	data = PostImportData{
 		Team:     ptrStr("teamname"),
@@ -842,7 +852,7 @@ func TestImportValidateDirectChannelImportData(t *testing.T) {
 	}
 
 	// Test with invalid Header.
-	data.Header = ptrStr(strings.Repeat("abcdefghij ", 103))
+	data.Header = ptrStr(strings.Repeat("abcdefghij ", 373))
 	if err := validateDirectChannelImportData(&data); err == nil {
 		t.Fatal("Should have failed due to too long header.")
 	}
@@ -1050,6 +1060,11 @@ func TestImportValidateDirectPostImportData(t *testing.T) {
 		t.Fatal("Should have failed due to too long message.")
 	}
 
+	data.Message = ptrStr("")
+	if err := validateDirectPostImportData(&data, maxPostSize); err == nil || err.Id != "app.import.validate_direct_post_import_data.message_empty.error" {
+		t.Fatal("Should have failed due to empty message.")
+	}
+
 	// Test with invalid CreateAt
 	data = DirectPostImportData{
 		ChannelMembers: &[]string{
@@ -0,0 +1,209 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func (a *App) CreateInviteLink(link *model.InviteLink) (*model.InviteLink, *model.AppError) {
+	if _, err := a.GetTeam(link.TeamId); err != nil {
+		return nil, err
+	}
+
+	if link.GuestOnly && !*a.Config().GuestAccountsSettings.Enable {
+		return nil, model.NewAppError("CreateInviteLink", "api.user.create_user.guest_accounts.disabled.app_error", nil, "", http.StatusNotImplemented)
+	}
+
+	link.PreSave()
+
+	result := <-a.Srv.Store.InviteLink().Save(link)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.InviteLink), nil
+}
+
+func (a *App) GetInviteLink(linkId string) (*model.InviteLink, *model.AppError) {
+	result := <-a.Srv.Store.InviteLink().Get(linkId)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.InviteLink), nil
+}
+
+func (a *App) GetInviteLinksForTeam(teamId string) ([]*model.InviteLink, *model.AppError) {
+	result := <-a.Srv.Store.InviteLink().GetForTeam(teamId)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.([]*model.InviteLink), nil
+}
+
+func (a *App) GetInviteLinkRedemptions(linkId string) ([]*model.InviteLinkRedemption, *model.AppError) {
+	result := <-a.Srv.Store.InviteLink().GetRedemptions(linkId)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.([]*model.InviteLinkRedemption), nil
+}
+
+// RevokeInviteLink stops a link from being used any further. Users who have already joined with it
+// stay on the team.
+func (a *App) RevokeInviteLink(linkId string) (*model.InviteLink, *model.AppError) {
+	link, err := a.GetInviteLink(linkId)
+	if err != nil {
+		return nil, err
+	}
+
+	if link.DeleteAt != 0 {
+		return link, nil
+	}
+
+	link.DeleteAt = model.GetMillis()
+	if result := <-a.Srv.Store.InviteLink().Revoke(link.Id, link.DeleteAt); result.Err != nil {
+		return nil, result.Err
+	}
+
+	return link, nil
+}
+
+// getUsableInviteLink returns the link with the given id along with its team if it can still be used.
+// Since the link may be revoked or used up at any time, redeemInviteLink checks this again when it
+// takes a use of it.
+func (a *App) getUsableInviteLink(linkId string) (*model.InviteLink, *model.Team, *model.AppError) {
+	link, err := a.GetInviteLink(linkId)
+	if err != nil {
+		return nil, nil, model.NewAppError("getUsableInviteLink", "app.invite_link.invalid.app_error", nil, err.Error(), http.StatusBadRequest)
+	}
+
+	if !link.IsUsable(model.GetMillis()) {
+		return nil, nil, model.NewAppError("getUsableInviteLink", "app.invite_link.unusable.app_error", nil, "id="+link.Id, http.StatusBadRequest)
+	}
+
+	team, err := a.GetTeam(link.TeamId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return link, team, nil
+}
+
+func (a *App) redeemInviteLink(link *model.InviteLink, userId string) *model.AppError {
+	if result := <-a.Srv.Store.InviteLink().Redeem(link.Id, userId, model.GetMillis()); result.Err != nil {
+		if result.Err.Id == "store.sql_invite_link.redeem.unavailable.app_error" {
+			return model.NewAppError("redeemInviteLink", "app.invite_link.unusable.app_error", nil, "id="+link.Id, http.StatusBadRequest)
+		}
+		return result.Err
+	}
+
+	return nil
+}
+
+func (a *App) AddUserToTeamByInviteLink(linkId string, userId string) (*model.Team, *model.AppError) {
+	link, team, err := a.getUsableInviteLink(linkId)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := a.GetUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsGuest() != link.GuestOnly {
+		return nil, model.NewAppError("AddUserToTeamByInviteLink", "api.user.create_user.invalid_invitation_type.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	// Following a link to a team that the user is already on shouldn't use it up
+	member, err := a.GetTeamMember(team.Id, user.Id)
+	if err == nil && member.DeleteAt == 0 {
+		return team, nil
+	}
+	rejoining := err == nil
+
+	if err := a.redeemInviteLink(link, user.Id); err != nil {
+		// A user who left the team after joining with the link has already redeemed it, so they can rejoin without
+		// using it up again
+		if !rejoining || err.Id != "store.sql_invite_link.redeem.exists.app_error" {
+			return nil, err
+		}
+	}
+
+	if err := a.JoinUserToTeam(team, user, ""); err != nil {
+		return nil, err
+	}
+
+	return team, nil
+}
+
+func (a *App) AddTeamMemberByInviteLink(linkId, userId string) (*model.TeamMember, *model.AppError) {
+	team, err := a.AddUserToTeamByInviteLink(linkId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.GetTeamMember(team.Id, userId)
+}
+
+func (a *App) CreateUserWithInviteLink(user *model.User, linkId string) (*model.User, *model.AppError) {
+	if err := a.IsUserSignUpAllowed(); err != nil {
+		return nil, err
+	}
+
+	link, team, err := a.getUsableInviteLink(linkId)
+	if err != nil {
+		return nil, err
+	}
+
+	user.EmailVerified = false
+
+	var ruser *model.User
+	if link.GuestOnly {
+		if !*a.Config().GuestAccountsSettings.Enable {
+			return nil, model.NewAppError("CreateUserWithInviteLink", "api.user.create_user.guest_accounts.disabled.app_error", nil, "", http.StatusNotImplemented)
+		}
+
+		if ruser, err = a.CreateGuest(user); err != nil {
+			return nil, err
+		}
+	} else if ruser, err = a.CreateUser(user); err != nil {
+		return nil, err
+	}
+
+	// The link may have been used up or revoked since it was checked, in which case the user is deleted again so
+	// that they aren't left with an account that isn't on any team
+	if err := a.redeemInviteLink(link, ruser.Id); err != nil {
+		a.deleteUserCreatedWithInviteLink(ruser)
+		return nil, err
+	}
+
+	if err := a.JoinUserToTeam(team, ruser, ""); err != nil {
+		a.deleteUserCreatedWithInviteLink(ruser)
+		return nil, err
+	}
+
+	if !link.GuestOnly {
+		a.AddDirectChannels(team.Id, ruser)
+	}
+
+	if err := a.SendWelcomeEmail(ruser.Id, ruser.Email, ruser.EmailVerified, ruser.Locale, a.GetSiteURL()); err != nil {
+		mlog.Error(err.Error())
+	}
+
+	return ruser, nil
+}
+
+func (a *App) deleteUserCreatedWithInviteLink(user *model.User) {
+	if err := a.PermanentDeleteUser(user); err != nil {
+		mlog.Error("Failed to delete a user who couldn't join a team with an invite link", mlog.String("user_id", user.Id), mlog.Err(err))
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestInviteLinkMaxUses(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	link, err := th.App.CreateInviteLink(&model.InviteLink{TeamId: th.BasicTeam.Id, CreatorId: th.BasicUser.Id, MaxUses: 2})
+	require.Nil(t, err)
+
+	user1 := th.CreateUser()
+	_, err = th.App.AddUserToTeamByInviteLink(link.Id, user1.Id)
+	require.Nil(t, err)
+
+	// joining again doesn't use the link up
+	_, err = th.App.AddUserToTeamByInviteLink(link.Id, user1.Id)
+	require.Nil(t, err)
+
+	user2, err := th.App.CreateUserWithInviteLink(&model.User{Email: th.MakeEmail(), Username: "n" + model.NewId(), Password: "passwd1"}, link.Id)
+	require.Nil(t, err)
+
+	_, err = th.App.GetTeamMember(th.BasicTeam.Id, user2.Id)
+	require.Nil(t, err)
+
+	user3 := th.CreateUser()
+	_, err = th.App.AddUserToTeamByInviteLink(link.Id, user3.Id)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "app.invite_link.unusable.app_error", err.Id)
+	}
+
+	_, err = th.App.GetTeamMember(th.BasicTeam.Id, user3.Id)
+	assert.NotNil(t, err)
+
+	link, err = th.App.GetInviteLink(link.Id)
+	require.Nil(t, err)
+	assert.Equal(t, 2, link.Uses)
+
+	redemptions, err := th.App.GetInviteLinkRedemptions(link.Id)
+	require.Nil(t, err)
+	require.Len(t, redemptions, 2)
+	assert.ElementsMatch(t, []string{user1.Id, user2.Id}, []string{redemptions[0].UserId, redemptions[1].UserId})
+}
+
+func TestInviteLinkRejoin(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	link, err := th.App.CreateInviteLink(&model.InviteLink{TeamId: th.BasicTeam.Id, CreatorId: th.BasicUser.Id, MaxUses: 1})
+	require.Nil(t, err)
+
+	user := th.CreateUser()
+	_, err = th.App.AddUserToTeamByInviteLink(link.Id, user.Id)
+	require.Nil(t, err)
+
+	require.Nil(t, th.App.RemoveUserFromTeam(th.BasicTeam.Id, user.Id, ""))
+
+	_, err = th.App.AddUserToTeamByInviteLink(link.Id, user.Id)
+	require.Nil(t, err, "a user who left the team can rejoin with the link that they joined with")
+
+	member, err := th.App.GetTeamMember(th.BasicTeam.Id, user.Id)
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), member.DeleteAt)
+
+	link, err = th.App.GetInviteLink(link.Id)
+	require.Nil(t, err)
+	assert.Equal(t, 1, link.Uses, "rejoining shouldn't use the link up again")
+}
+
+func TestCreateUserWithUsedUpInviteLink(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	link, err := th.App.CreateInviteLink(&model.InviteLink{TeamId: th.BasicTeam.Id, CreatorId: th.BasicUser.Id, MaxUses: 1})
+	require.Nil(t, err)
+
+	// Some of the users may pass the first check of the link before it's used up
+	emails := make([]string, 5)
+	errs := make([]*model.AppError, len(emails))
+	var wg sync.WaitGroup
+	for i := range emails {
+		emails[i] = th.MakeEmail()
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = th.App.CreateUserWithInviteLink(&model.User{Email: emails[i], Username: "n" + model.NewId(), Password: "passwd1"}, link.Id)
+		}(i)
+	}
+	wg.Wait()
+
+	created := 0
+	for i, err := range errs {
+		if err == nil {
+			created++
+			continue
+		}
+
+		assert.Equal(t, "app.invite_link.unusable.app_error", err.Id)
+		_, err = th.App.GetUserByEmail(emails[i])
+		assert.NotNil(t, err, "users who couldn't join the team shouldn't be left behind")
+	}
+	assert.Equal(t, 1, created)
+}
+
+func TestInviteLinkExpiry(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	link, err := th.App.CreateInviteLink(&model.InviteLink{TeamId: th.BasicTeam.Id, CreatorId: th.BasicUser.Id, ExpiresAt: model.GetMillis() + 1000})
+	require.Nil(t, err)
+
+	user1 := th.CreateUser()
+	_, err = th.App.AddUserToTeamByInviteLink(link.Id, user1.Id)
+	require.Nil(t, err)
+
+	time.Sleep(1100 * time.Millisecond)
+
+	user := th.CreateUser()
+	_, err = th.App.AddUserToTeamByInviteLink(link.Id, user.Id)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "app.invite_link.unusable.app_error", err.Id)
+	}
+
+	_, err = th.App.CreateUserWithInviteLink(&model.User{Email: th.MakeEmail(), Username: "n" + model.NewId(), Password: "passwd1"}, link.Id)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "app.invite_link.unusable.app_error", err.Id)
+	}
+}
+
+func TestInviteLinkRevocation(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	link, err := th.App.CreateInviteLink(&model.InviteLink{TeamId: th.BasicTeam.Id, CreatorId: th.BasicUser.Id})
+	require.Nil(t, err)
+
+	user1 := th.CreateUser()
+	_, err = th.App.AddUserToTeamByInviteLink(link.Id, user1.Id)
+	require.Nil(t, err)
+
+	t.Run("revoked after being looked up", func(t *testing.T) {
+		usable, _, err := th.App.getUsableInviteLink(link.Id)
+		require.Nil(t, err)
+
+		_, err = th.App.RevokeInviteLink(link.Id)
+		require.Nil(t, err)
+
+		user2 := th.CreateUser()
+		err = th.App.redeemInviteLink(usable, user2.Id)
+		if assert.NotNil(t, err) {
+			assert.Equal(t, "app.invite_link.unusable.app_error", err.Id)
+		}
+	})
+
+	t.Run("revoked", func(t *testing.T) {
+		user3 := th.CreateUser()
+		_, err = th.App.AddUserToTeamByInviteLink(link.Id, user3.Id)
+		if assert.NotNil(t, err) {
+			assert.Equal(t, "app.invite_link.unusable.app_error", err.Id)
+		}
+	})
+
+	// users who already joined stay on the team
+	member, err := th.App.GetTeamMember(th.BasicTeam.Id, user1.Id)
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), member.DeleteAt)
+
+	links, err := th.App.GetInviteLinksForTeam(th.BasicTeam.Id)
+	require.Nil(t, err)
+	require.Len(t, links, 1)
+	assert.NotEqual(t, int64(0), links[0].DeleteAt)
+	assert.Equal(t, 1, links[0].Uses)
+}
+
+func TestInviteLinkGuestOnly(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	_, err := th.App.CreateInviteLink(&model.InviteLink{TeamId: th.BasicTeam.Id, CreatorId: th.BasicUser.Id, GuestOnly: true})
+	require.NotNil(t, err)
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.GuestAccountsSettings.Enable = true })
+
+	link, err := th.App.CreateInviteLink(&model.InviteLink{TeamId: th.BasicTeam.Id, CreatorId: th.BasicUser.Id, GuestOnly: true})
+	require.Nil(t, err)
+
+	_, err = th.App.AddUserToTeamByInviteLink(link.Id, th.CreateUser().Id)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "api.user.create_user.invalid_invitation_type.app_error", err.Id)
+	}
+
+	guest, err := th.App.CreateUserWithInviteLink(&model.User{Email: th.MakeEmail(), Username: "g" + model.NewId(), Password: "passwd1"}, link.Id)
+	require.Nil(t, err)
+	assert.True(t, guest.IsGuest())
+
+	_, err = th.App.GetTeamMember(th.BasicTeam.Id, guest.Id)
+	assert.Nil(t, err)
+}
+
+func TestDisabledInviteId(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	team := th.CreateTeam()
+	team.InviteIdDisabled = true
+	team, err := th.App.UpdateTeam(team)
+	require.Nil(t, err)
+
+	_, err = th.App.AddUserToTeamByInviteId(team.InviteId, th.BasicUser.Id)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "app.team.invite_id_disabled.app_error", err.Id)
+	}
+
+	_, err = th.App.CreateUserWithInviteId(&model.User{Email: th.MakeEmail(), Username: "n" + model.NewId(), Password: "passwd1"}, team.InviteId)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "app.team.invite_id_disabled.app_error", err.Id)
+	}
+
+	// links keep working for the team
+	link, err := th.App.CreateInviteLink(&model.InviteLink{TeamId: team.Id, CreatorId: th.BasicUser.Id})
+	require.Nil(t, err)
+
+	_, err = th.App.AddUserToTeamByInviteLink(link.Id, th.BasicUser.Id)
+	assert.Nil(t, err)
+}
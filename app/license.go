@@ -93,8 +93,8 @@ func (a *App) SaveLicense(licenseBytes []byte) (*model.License, *model.AppError)
 
 	// start job server if necessary - this handles the edge case where a license file is uploaded, but the job server
 	// doesn't start until the server is restarted, which prevents the 'run job now' buttons in system console from
-	// functioning as expected
-	if *a.Config().JobSettings.RunJobs {
+	// functioning as expected. A server that's being drained has stopped running jobs for good though.
+	if *a.Config().JobSettings.RunJobs && !a.IsDraining() {
 		a.Jobs.StartWorkers()
 	}
 	if *a.Config().JobSettings.RunScheduler {
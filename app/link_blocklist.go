@@ -0,0 +1,203 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+func (a *App) GetBlockedDomains() ([]*model.BlockedDomain, *model.AppError) {
+	if result := <-a.Srv.Store.BlockedDomain().GetAll(); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return result.Data.([]*model.BlockedDomain), nil
+	}
+}
+
+// AddBlockedDomains adds domains to the link blocklist and returns the ones that weren't already on it. Domains are
+// saved in lowercase with internationalized names in punycode so that they're matched however links are written.
+func (a *App) AddBlockedDomains(domains []string, creatorId string) ([]*model.BlockedDomain, *model.AppError) {
+	existing, err := a.GetBlockedDomains()
+	if err != nil {
+		return nil, err
+	}
+
+	blocked := make(map[string]bool, len(existing))
+	for _, domain := range existing {
+		blocked[domain.Domain] = true
+	}
+
+	var toAdd []string
+	for _, domain := range domains {
+		normalized, ok := model.NormalizeBlockedDomain(domain)
+		if !ok {
+			return nil, model.NewAppError("AddBlockedDomains", "app.link_blocklist.add.domain.app_error", map[string]interface{}{"Domain": domain}, "", http.StatusBadRequest)
+		}
+
+		if !blocked[normalized] && !utils.StringInSlice(normalized, toAdd) {
+			toAdd = append(toAdd, normalized)
+		}
+	}
+
+	if len(existing)+len(toAdd) > model.BLOCKED_DOMAINS_MAX_COUNT {
+		return nil, model.NewAppError("AddBlockedDomains", "app.link_blocklist.add.too_many.app_error", map[string]interface{}{"Max": model.BLOCKED_DOMAINS_MAX_COUNT}, "", http.StatusBadRequest)
+	}
+
+	// The blocklist is invalidated even if saving fails part way through so that the domains that were saved apply
+	defer func() {
+		if len(toAdd) > 0 {
+			a.InvalidateLinkBlocklist()
+		}
+	}()
+
+	added := []*model.BlockedDomain{}
+	for _, domain := range toAdd {
+		result := <-a.Srv.Store.BlockedDomain().Save(&model.BlockedDomain{Domain: domain, CreatorId: creatorId})
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		added = append(added, result.Data.(*model.BlockedDomain))
+	}
+
+	return added, nil
+}
+
+// RemoveBlockedDomains removes domains from the link blocklist. Domains that aren't on it are ignored.
+func (a *App) RemoveBlockedDomains(domains []string) *model.AppError {
+	defer a.InvalidateLinkBlocklist()
+
+	for _, domain := range domains {
+		normalized, ok := model.NormalizeBlockedDomain(domain)
+		if !ok {
+			continue
+		}
+
+		if result := <-a.Srv.Store.BlockedDomain().Delete(normalized); result.Err != nil {
+			return result.Err
+		}
+	}
+
+	return nil
+}
+
+// InvalidateLinkBlocklist makes every server in the cluster reload the link blocklist the next time that it's used.
+func (a *App) InvalidateLinkBlocklist() {
+	a.InvalidateLinkBlocklistSkipClusterSend()
+
+	if a.Cluster != nil {
+		a.Cluster.SendClusterMessage(&model.ClusterMessage{
+			Event:    model.CLUSTER_EVENT_INVALIDATE_CACHE_FOR_LINK_BLOCKLIST,
+			SendType: model.CLUSTER_SEND_RELIABLE,
+		})
+	}
+}
+
+func (a *App) InvalidateLinkBlocklistSkipClusterSend() {
+	a.linkBlocklistLock.Lock()
+	a.linkBlocklist = nil
+	a.linkBlocklistLock.Unlock()
+}
+
+func (a *App) getLinkBlocklist() (*model.LinkBlocklist, *model.AppError) {
+	a.linkBlocklistLock.RLock()
+	blocklist := a.linkBlocklist
+	a.linkBlocklistLock.RUnlock()
+
+	if blocklist != nil {
+		return blocklist, nil
+	}
+
+	a.linkBlocklistLock.Lock()
+	defer a.linkBlocklistLock.Unlock()
+
+	if a.linkBlocklist != nil {
+		return a.linkBlocklist, nil
+	}
+
+	domains, err := a.GetBlockedDomains()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		names = append(names, domain.Domain)
+	}
+
+	a.linkBlocklist = model.NewLinkBlocklist(names)
+
+	return a.linkBlocklist, nil
+}
+
+// checkPostLinks checks the links in the message of a post that's about to be saved against the link blocklist,
+// turning them into plain text in the post or returning an error if the post should be rejected because of them.
+// When the author should be warned instead, the blocked domains that were linked to are returned so that
+// warnAboutBlockedLinks can be called once the post is saved.
+func (a *App) checkPostLinks(post *model.Post) ([]string, *model.AppError) {
+	mode := *a.Config().ServiceSettings.LinkBlocklistMode
+	if mode == model.LINK_BLOCKLIST_MODE_OFF || post.IsSystemMessage() || post.Message == "" {
+		return nil, nil
+	}
+
+	blocklist, err := a.getLinkBlocklist()
+	if err != nil {
+		return nil, err
+	} else if blocklist.IsEmpty() {
+		return nil, nil
+	}
+
+	var blockedLinks []*model.MessageLink
+	var domains []string
+	for _, link := range model.FindMessageLinks(post.Message) {
+		if domain := blocklist.Match(link.Host); domain != "" {
+			blockedLinks = append(blockedLinks, link)
+			if !utils.StringInSlice(domain, domains) {
+				domains = append(domains, domain)
+			}
+		}
+	}
+
+	if len(blockedLinks) == 0 {
+		return nil, nil
+	}
+
+	switch mode {
+	case model.LINK_BLOCKLIST_MODE_STRIP:
+		post.Message = model.StripMessageLinks(post.Message, blockedLinks)
+		post.Hashtags, _ = model.ParseHashtags(post.Message)
+		return nil, nil
+	case model.LINK_BLOCKLIST_MODE_BLOCK:
+		return nil, model.NewAppError("checkPostLinks", "app.post.link_blocklist.blocked.app_error", map[string]interface{}{"Domains": strings.Join(domains, ", ")}, "user_id="+post.UserId+", channel_id="+post.ChannelId, http.StatusBadRequest)
+	}
+
+	return domains, nil
+}
+
+// warnAboutBlockedLinks lets the author of a saved post know that it links to blocked domains.
+func (a *App) warnAboutBlockedLinks(post *model.Post, domains []string) {
+	if len(domains) == 0 {
+		return
+	}
+
+	user, err := a.GetUser(post.UserId)
+	if err != nil {
+		mlog.Error(fmt.Sprintf("Unable to warn about blocked links, post_id=%v, err=%v", post.Id, err.Error()), mlog.String("post_id", post.Id))
+		return
+	}
+
+	a.SendEphemeralPost(
+		post.UserId,
+		&model.Post{
+			ChannelId: post.ChannelId,
+			Message:   utils.GetUserTranslations(user.Locale)("app.post.link_blocklist.warning", map[string]interface{}{"Domains": strings.Join(domains, ", ")}),
+			CreateAt:  post.CreateAt + 1,
+		},
+	)
+}
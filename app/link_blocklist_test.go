@@ -0,0 +1,162 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestBlockedDomains(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	prefix := model.NewId()
+
+	added, err := th.App.AddBlockedDomains([]string{prefix + ".Example.com", "*." + prefix + ".example.com", prefix + ".example.com", prefix + ".bücher.example"}, th.BasicUser.Id)
+	require.Nil(t, err)
+	require.Len(t, added, 3)
+	assert.Equal(t, prefix+".example.com", added[0].Domain)
+	assert.Equal(t, "*."+prefix+".example.com", added[1].Domain)
+	assert.Equal(t, prefix+".xn--bcher-kva.example", added[2].Domain)
+	assert.Equal(t, th.BasicUser.Id, added[0].CreatorId)
+
+	added, err = th.App.AddBlockedDomains([]string{prefix + ".example.com"}, th.BasicUser.Id)
+	require.Nil(t, err)
+	assert.Empty(t, added, "domains that are already blocked should be skipped")
+
+	_, err = th.App.AddBlockedDomains([]string{"https://" + prefix + ".example.com/path"}, th.BasicUser.Id)
+	require.NotNil(t, err)
+	assert.Equal(t, "app.link_blocklist.add.domain.app_error", err.Id)
+
+	require.Nil(t, th.App.RemoveBlockedDomains([]string{prefix + ".EXAMPLE.com", prefix + ".bücher.example", "not a domain"}))
+
+	domains, err := th.App.GetBlockedDomains()
+	require.Nil(t, err)
+
+	var names []string
+	for _, domain := range domains {
+		names = append(names, domain.Domain)
+	}
+	assert.Contains(t, names, "*."+prefix+".example.com")
+	assert.NotContains(t, names, prefix+".example.com")
+	assert.NotContains(t, names, prefix+".xn--bcher-kva.example")
+}
+
+func TestCreatePostLinkBlocklist(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	settings := th.App.Config().ServiceSettings
+	defer th.App.UpdateConfig(func(cfg *model.Config) {
+		cfg.ServiceSettings.LinkBlocklistMode = settings.LinkBlocklistMode
+	})
+
+	domain := model.NewId() + ".com"
+	_, err := th.App.AddBlockedDomains([]string{domain, "*." + domain}, th.BasicUser.Id)
+	require.Nil(t, err)
+
+	setMode := func(mode string) {
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.LinkBlocklistMode = mode })
+	}
+
+	createPost := func(message string) (*model.Post, *model.AppError) {
+		return th.App.CreatePostAsUser(&model.Post{
+			ChannelId: th.BasicChannel.Id,
+			UserId:    th.BasicUser.Id,
+			Message:   message,
+		})
+	}
+
+	message := "sign in at [the portal](https://" + domain + "/login) or www." + domain
+
+	t.Run("off", func(t *testing.T) {
+		setMode(model.LINK_BLOCKLIST_MODE_OFF)
+
+		post, err := createPost(message)
+		require.Nil(t, err)
+		assert.Equal(t, message, post.Message)
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		setMode(model.LINK_BLOCKLIST_MODE_WARN)
+
+		post, err := createPost(message)
+		require.Nil(t, err)
+		assert.Equal(t, message, post.Message)
+
+		domains, err := th.App.checkPostLinks(&model.Post{UserId: th.BasicUser.Id, Message: message})
+		require.Nil(t, err)
+		assert.Equal(t, []string{domain, "*." + domain}, domains)
+	})
+
+	t.Run("strip", func(t *testing.T) {
+		setMode(model.LINK_BLOCKLIST_MODE_STRIP)
+
+		post, err := createPost(message + " #phish")
+		require.Nil(t, err)
+		assert.Equal(t, "sign in at the portal or `www."+domain+"` #phish", post.Message)
+		assert.Equal(t, "#phish", post.Hashtags)
+
+		post, err = createPost("[see https://" + domain + "](https://example.com)")
+		require.Nil(t, err)
+		assert.Equal(t, "[see `https://"+domain+"`](https://example.com)", post.Message, "should strip links nested in other links")
+	})
+
+	t.Run("block", func(t *testing.T) {
+		setMode(model.LINK_BLOCKLIST_MODE_BLOCK)
+
+		_, err := createPost(message)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.post.link_blocklist.blocked.app_error", err.Id)
+
+		_, err = createPost("https://" + domain[:len(domain)-4] + ".net")
+		require.Nil(t, err)
+
+		post, err := createPost("https://example.com")
+		require.Nil(t, err)
+
+		post.Message = "[https://example.com](http://sub." + domain + ")"
+		_, err = th.App.UpdatePost(post, false)
+		require.NotNil(t, err, "should check edits")
+		assert.Equal(t, "app.post.link_blocklist.blocked.app_error", err.Id)
+	})
+
+	t.Run("homoglyphs", func(t *testing.T) {
+		setMode(model.LINK_BLOCKLIST_MODE_BLOCK)
+
+		_, err := th.App.AddBlockedDomains([]string{"paypal.com"}, th.BasicUser.Id)
+		require.Nil(t, err)
+		defer th.App.RemoveBlockedDomains([]string{"paypal.com"})
+
+		for _, host := range []string{"pаypal.com", "xn--pypal-4ve.com", "PAYPAL.com", "ｐａｙｐａｌ.com", "pàypal.com"} {
+			_, err := createPost("log in at https://" + host + "/account")
+			require.NotNil(t, err, host)
+			assert.Equal(t, "app.post.link_blocklist.blocked.app_error", err.Id, host)
+		}
+	})
+
+	t.Run("invalidated when changed", func(t *testing.T) {
+		setMode(model.LINK_BLOCKLIST_MODE_BLOCK)
+
+		other := model.NewId() + ".org"
+		_, err := createPost("https://" + other)
+		require.Nil(t, err)
+
+		_, err = th.App.AddBlockedDomains([]string{other}, th.BasicUser.Id)
+		require.Nil(t, err)
+
+		_, err = createPost("https://" + other)
+		require.NotNil(t, err)
+
+		require.Nil(t, th.App.RemoveBlockedDomains([]string{other}))
+
+		_, err = createPost("https://" + other)
+		require.Nil(t, err)
+	})
+}
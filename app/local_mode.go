@@ -0,0 +1,88 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/gorilla/handlers"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// startLocalModeServer serves LocalRouter over a unix socket that only the user running the server can connect to.
+// Requests over it are made as a system admin without a session, so that the CLI can use the running server instead
+// of starting one of its own.
+func (a *App) startLocalModeServer() error {
+	socket := *a.Config().ServiceSettings.LocalModeSocketLocation
+
+	if info, err := os.Lstat(socket); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return fmt.Errorf("unable to start local mode: %v exists and isn't a socket", socket)
+		}
+
+		if err := checkLocalModeSocketOwner(socket, info); err != nil {
+			return err
+		}
+
+		if conn, err := net.Dial("unix", socket); err == nil {
+			conn.Close()
+			return fmt.Errorf("unable to start local mode: another server is listening on %v", socket)
+		}
+
+		// Left behind by a server that didn't shut down cleanly
+		if err := os.Remove(socket); err != nil {
+			return errors.Wrapf(err, "unable to remove the old local mode socket %v", socket)
+		}
+	}
+
+	listener, err := listenLocalModeSocket(socket)
+	if err != nil {
+		return errors.Wrapf(err, "unable to start local mode on %v", socket)
+	}
+
+	if err := os.Chmod(socket, 0600); err != nil {
+		listener.Close()
+		return errors.Wrapf(err, "unable to set the permissions of the local mode socket %v", socket)
+	}
+
+	a.Srv.localServer = &http.Server{
+		Handler:  handlers.RecoveryHandler(handlers.RecoveryLogger(&RecoveryLogger{}), handlers.PrintRecoveryStack(true))(a.Srv.LocalRouter),
+		ErrorLog: a.Log.StdLog(mlog.String("source", "localserver")),
+	}
+
+	mlog.Info(fmt.Sprintf("Local mode is listening on %v", socket))
+
+	go func(server *http.Server) {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			mlog.Error("Local mode server stopped", mlog.Err(err))
+		}
+	}(a.Srv.localServer)
+
+	return nil
+}
+
+// LocalModeSession is the session that requests over the local mode socket are made with. It has no user, and has the
+// permissions of a system admin.
+func LocalModeSession() model.Session {
+	return model.Session{
+		Roles: model.SYSTEM_ADMIN_ROLE_ID + " " + model.SYSTEM_USER_ROLE_ID,
+		Props: model.StringMap{},
+	}
+}
+
+func (a *App) stopLocalModeServer() {
+	if a.Srv.localServer == nil {
+		return
+	}
+
+	// Closing the listener removes the socket file
+	a.Srv.localServer.Close()
+	a.Srv.localServer = nil
+}
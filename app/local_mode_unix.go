@@ -0,0 +1,37 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+// +build !windows
+
+package app
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// listenLocalModeSocket creates the socket with a umask that only lets the user running the server use it, so that
+// there's no window between creating it and changing its permissions in which someone else can connect.
+func listenLocalModeSocket(socket string) (net.Listener, error) {
+	oldMask := syscall.Umask(0177)
+	defer syscall.Umask(oldMask)
+
+	return net.Listen("unix", socket)
+}
+
+// checkLocalModeSocketOwner refuses a socket left at the configured location by another user, since they could be
+// listening on it to receive the CLI's requests.
+func checkLocalModeSocketOwner(socket string, info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("unable to start local mode: unable to find the owner of %v", socket)
+	}
+
+	if int(stat.Uid) != os.Geteuid() {
+		return fmt.Errorf("unable to start local mode: %v is owned by another user", socket)
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+// +build !windows
+
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenLocalModeSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "localmode")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "local.socket")
+
+	listener, err := listenLocalModeSocket(socket)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	info, err := os.Lstat(socket)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "the socket should be created without access for other users")
+	assert.NoError(t, checkLocalModeSocketOwner(socket, info))
+}
+
+func TestCheckLocalModeSocketOwner(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing the owner of a file requires running as root")
+	}
+
+	dir, err := ioutil.TempDir("", "localmode")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "local.socket")
+	require.NoError(t, ioutil.WriteFile(socket, nil, 0600))
+	require.NoError(t, os.Chown(socket, 12345, 12345))
+
+	info, err := os.Lstat(socket)
+	require.NoError(t, err)
+	assert.Error(t, checkLocalModeSocketOwner(socket, info))
+}
@@ -0,0 +1,19 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net"
+	"os"
+)
+
+// listenLocalModeSocket creates the socket. Windows doesn't have a umask, so its permissions are only set afterwards.
+func listenLocalModeSocket(socket string) (net.Listener, error) {
+	return net.Listen("unix", socket)
+}
+
+// checkLocalModeSocketOwner does nothing since Windows doesn't report the owner of a file through os.FileInfo.
+func checkLocalModeSocketOwner(socket string, info os.FileInfo) error {
+	return nil
+}
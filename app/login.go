@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/avct/uasurfer"
+	"github.com/mattermost/mattermost-server/mlog"
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/store"
 )
@@ -74,24 +75,41 @@ func (a *App) GetUserForLogin(id, loginId string) (*model.User, *model.AppError)
 		}
 	}
 
+	if enableEmail {
+		if err := a.checkPendingEmailChangeForLogin(loginId); err != nil {
+			return nil, err
+		}
+	}
+
 	return nil, model.NewAppError("GetUserForLogin", "store.sql_user.get_for_login.app_error", nil, "", http.StatusBadRequest)
 }
 
+// GetSessionLengthInDays returns how long a session lasts for a user signing in with the given method. Mobile
+// sessions use the mobile length, users that sign in through GitLab, SAML or another identity provider use the
+// SSO length, and users that sign in with their email address or AD/LDAP credentials use the web length.
+func (a *App) GetSessionLengthInDays(user *model.User, deviceId string) int {
+	if len(deviceId) > 0 {
+		return *a.Config().ServiceSettings.SessionLengthMobileInDays
+	} else if user.IsSSOUser() && !user.IsLDAPUser() {
+		return *a.Config().ServiceSettings.SessionLengthSSOInDays
+	}
+
+	return *a.Config().ServiceSettings.SessionLengthWebInDays
+}
+
 func (a *App) DoLogin(w http.ResponseWriter, r *http.Request, user *model.User, deviceId string) (*model.Session, *model.AppError) {
 	session := &model.Session{UserId: user.Id, Roles: user.GetRawRoles(), DeviceId: deviceId, IsOAuth: false}
 
-	maxAge := *a.Config().ServiceSettings.SessionLengthWebInDays * 60 * 60 * 24
+	sessionLength := a.GetSessionLengthInDays(user, deviceId)
+	maxAge := sessionLength * 60 * 60 * 24
+	session.SetExpireInDays(sessionLength)
 
 	if len(deviceId) > 0 {
-		session.SetExpireInDays(*a.Config().ServiceSettings.SessionLengthMobileInDays)
-
 		// A special case where we logout of all other sessions with the same Id
 		if err := a.RevokeSessionsForDeviceId(user.Id, deviceId, ""); err != nil {
 			err.StatusCode = http.StatusInternalServerError
 			return nil, err
 		}
-	} else {
-		session.SetExpireInDays(*a.Config().ServiceSettings.SessionLengthWebInDays)
 	}
 
 	ua := uasurfer.Parse(r.UserAgent())
@@ -104,6 +122,7 @@ func (a *App) DoLogin(w http.ResponseWriter, r *http.Request, user *model.User,
 	session.AddProp(model.SESSION_PROP_PLATFORM, plat)
 	session.AddProp(model.SESSION_PROP_OS, os)
 	session.AddProp(model.SESSION_PROP_BROWSER, fmt.Sprintf("%v/%v", bname, bversion))
+	a.AddSessionFingerprint(session, r)
 
 	var err *model.AppError
 	if session, err = a.CreateSession(session); err != nil {
@@ -111,7 +130,14 @@ func (a *App) DoLogin(w http.ResponseWriter, r *http.Request, user *model.User,
 		return nil, err
 	}
 
+	if result := <-a.Srv.Store.User().UpdateLastSeenAt(user.Id, session.CreateAt); result.Err != nil {
+		mlog.Error(fmt.Sprintf("Failed to update LastSeenAt for user_id=%v, err=%v", user.Id, result.Err), mlog.String("user_id", user.Id))
+	}
+
 	w.Header().Set(model.HEADER_TOKEN, session.Token)
+	if session.TermsOfServiceRequired {
+		w.Header().Set(model.HEADER_TERMS_OF_SERVICE_REQUIRED, "true")
+	}
 
 	secure := false
 	if GetProtocol(r) == "https" {
@@ -0,0 +1,57 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestDoLoginSessionLength(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.ServiceSettings.SessionLengthWebInDays = 30
+		*cfg.ServiceSettings.SessionLengthSSOInDays = 7
+		*cfg.ServiceSettings.SessionLengthMobileInDays = 90
+	})
+
+	day := int64(24 * 60 * 60 * 1000)
+
+	for name, tc := range map[string]struct {
+		AuthService string
+		DeviceId    string
+		Days        int64
+	}{
+		"email":       {"", "", 30},
+		"ldap":        {model.USER_AUTH_SERVICE_LDAP, "", 30},
+		"gitlab":      {model.USER_AUTH_SERVICE_GITLAB, "", 7},
+		"saml":        {model.USER_AUTH_SERVICE_SAML, "", 7},
+		"mobile":      {"", "android:" + model.NewId(), 90},
+		"mobile saml": {model.USER_AUTH_SERVICE_SAML, "android:" + model.NewId(), 90},
+	} {
+		t.Run(name, func(t *testing.T) {
+			user := &model.User{AuthService: tc.AuthService}
+			assert.Equal(t, int(tc.Days), th.App.GetSessionLengthInDays(user, tc.DeviceId))
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "/api/v4/users/login", nil)
+
+			user.Id = th.BasicUser.Id
+			session, err := th.App.DoLogin(w, r, user, tc.DeviceId)
+			require.Nil(t, err)
+			assert.Equal(t, tc.Days*day, session.ExpiresAt-session.CreateAt)
+
+			cookie := w.Result().Cookies()[0]
+			assert.Equal(t, model.SESSION_COOKIE_TOKEN, cookie.Name)
+			assert.Equal(t, int(tc.Days*24*60*60), cookie.MaxAge)
+		})
+	}
+}
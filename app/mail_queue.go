@@ -0,0 +1,207 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const (
+	MAIL_PRIORITY_HIGH = iota
+	MAIL_PRIORITY_LOW
+)
+
+// mailQueue sends emails in the background one at a time, no faster than the configured rate, so that sending a lot
+// of them at once, such as invitations to a whole list of people, doesn't hold up the request that queued them or
+// get them rejected by the SMTP server for being sent too quickly. Emails with a high priority are sent before any
+// low priority ones that are still waiting.
+type mailQueue struct {
+	lock    sync.Mutex
+	high    []*queuedMail
+	low     []*queuedMail
+	stopped bool
+
+	wake chan struct{}
+	stop chan struct{}
+	done sync.WaitGroup
+
+	// interval returns how long to wait after sending an email before sending the next one
+	interval func() time.Duration
+}
+
+type queuedMail struct {
+	send func() *model.AppError
+
+	// finish is called with the result of sending the email, or with an error if the queue was stopped before it
+	// could be sent
+	finish func(err *model.AppError)
+}
+
+func newMailQueue(interval func() time.Duration) *mailQueue {
+	q := &mailQueue{
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		interval: interval,
+	}
+
+	q.done.Add(1)
+	go q.work()
+
+	return q
+}
+
+func (q *mailQueue) work() {
+	defer q.done.Done()
+
+	var lastSent time.Time
+	for {
+		// The wait comes before taking the next email so that one with a high priority that's queued in the
+		// meantime still goes first
+		if wait := time.Until(lastSent.Add(q.interval())); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-q.stop:
+				timer.Stop()
+				return
+			}
+		}
+
+		mail := q.next()
+		for mail == nil {
+			select {
+			case <-q.wake:
+			case <-q.stop:
+				return
+			}
+			mail = q.next()
+		}
+
+		lastSent = time.Now()
+		mail.process()
+	}
+}
+
+func (mail *queuedMail) process() {
+	err := mail.send()
+	if mail.finish != nil {
+		mail.finish(err)
+	} else if err != nil {
+		mlog.Error(fmt.Sprintf("Failed to send queued email err=%v", err.Error()))
+	}
+}
+
+func (q *mailQueue) next() *queuedMail {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	var mail *queuedMail
+	if len(q.high) > 0 {
+		mail, q.high = q.high[0], q.high[1:]
+	} else if len(q.low) > 0 {
+		mail, q.low = q.low[0], q.low[1:]
+	}
+
+	return mail
+}
+
+// queue adds the email to the end of the emails with the same priority. It's sent immediately if the queue has been
+// stopped.
+func (q *mailQueue) queue(mail *queuedMail, priority int) {
+	q.lock.Lock()
+
+	if q.stopped {
+		q.lock.Unlock()
+		mail.process()
+		return
+	}
+
+	if priority == MAIL_PRIORITY_HIGH {
+		q.high = append(q.high, mail)
+	} else {
+		q.low = append(q.low, mail)
+	}
+
+	q.lock.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// length returns how many emails are waiting to be sent.
+func (q *mailQueue) length() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return len(q.high) + len(q.low)
+}
+
+// stopQueue waits for the email being sent, if any, to finish. The emails that are still waiting aren't sent, since
+// that could take a long time at the configured rate, but they're finished with an error so that whatever queued
+// them knows.
+func (q *mailQueue) stopQueue() {
+	q.lock.Lock()
+	if q.stopped {
+		q.lock.Unlock()
+		return
+	}
+	q.stopped = true
+	close(q.stop)
+	q.lock.Unlock()
+
+	q.done.Wait()
+
+	q.lock.Lock()
+	unsent := append(q.high, q.low...)
+	q.high = nil
+	q.low = nil
+	q.lock.Unlock()
+
+	for _, mail := range unsent {
+		if mail.finish != nil {
+			mail.finish(model.NewAppError("mailQueue.stopQueue", "app.mail_queue.stopped.app_error", nil, "", http.StatusServiceUnavailable))
+		}
+	}
+}
+
+func (a *App) startMailQueue() {
+	a.mailQueue = newMailQueue(func() time.Duration {
+		perMinute := *a.Config().EmailSettings.QueuedEmailsPerMinute
+		if perMinute <= 0 {
+			return 0
+		}
+
+		return time.Minute / time.Duration(perMinute)
+	})
+}
+
+func (a *App) stopMailQueue() {
+	if a.mailQueue != nil {
+		a.mailQueue.stopQueue()
+	}
+}
+
+// queueMail sends an email in the background at the configured rate, calling finish with the result once it's been
+// sent. The email is sent immediately if the queue isn't running.
+func (a *App) queueMail(priority int, send func() *model.AppError, finish func(err *model.AppError)) {
+	mail := &queuedMail{
+		send:   send,
+		finish: finish,
+	}
+
+	if a.mailQueue == nil {
+		mail.process()
+		return
+	}
+
+	a.mailQueue.queue(mail, priority)
+}
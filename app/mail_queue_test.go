@@ -0,0 +1,124 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestMailQueueRateLimit(t *testing.T) {
+	interval := 50 * time.Millisecond
+	q := newMailQueue(func() time.Duration { return interval })
+	defer q.stopQueue()
+
+	var lock sync.Mutex
+	var sentAt []time.Time
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		q.queue(&queuedMail{
+			send: func() *model.AppError {
+				lock.Lock()
+				sentAt = append(sentAt, time.Now())
+				lock.Unlock()
+				return nil
+			},
+			finish: func(err *model.AppError) {
+				assert.Nil(t, err)
+				wg.Done()
+			},
+		}, MAIL_PRIORITY_LOW)
+	}
+	wg.Wait()
+
+	require.Len(t, sentAt, 3)
+	for i := 1; i < len(sentAt); i++ {
+		assert.True(t, sentAt[i].Sub(sentAt[i-1]) >= interval, "emails should be sent no faster than the rate")
+	}
+	assert.Equal(t, 0, q.length())
+}
+
+func TestMailQueuePriority(t *testing.T) {
+	q := newMailQueue(func() time.Duration { return 50 * time.Millisecond })
+	defer q.stopQueue()
+
+	var lock sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	queue := func(name string, priority int) {
+		wg.Add(1)
+		q.queue(&queuedMail{
+			send: func() *model.AppError {
+				lock.Lock()
+				order = append(order, name)
+				lock.Unlock()
+				return nil
+			},
+			finish: func(*model.AppError) { wg.Done() },
+		}, priority)
+	}
+
+	// The first email is sent straight away, and the rest wait behind it
+	queue("low1", MAIL_PRIORITY_LOW)
+	waitForMailQueueLength(t, q, 0)
+	queue("low2", MAIL_PRIORITY_LOW)
+	queue("low3", MAIL_PRIORITY_LOW)
+	queue("high", MAIL_PRIORITY_HIGH)
+	wg.Wait()
+
+	assert.Equal(t, []string{"low1", "high", "low2", "low3"}, order)
+}
+
+func TestMailQueueStop(t *testing.T) {
+	q := newMailQueue(func() time.Duration { return time.Hour })
+
+	sent := 0
+	var errs []*model.AppError
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		q.queue(&queuedMail{
+			send: func() *model.AppError {
+				sent++
+				return nil
+			},
+			finish: func(err *model.AppError) {
+				errs = append(errs, err)
+				wg.Done()
+			},
+		}, MAIL_PRIORITY_LOW)
+	}
+
+	// Only the first email can be sent before the queue has to wait for the rate
+	waitForMailQueueLength(t, q, 2)
+	q.stopQueue()
+	wg.Wait()
+
+	assert.Equal(t, 1, sent)
+	require.Len(t, errs, 3)
+	assert.Nil(t, errs[0])
+	require.NotNil(t, errs[1])
+	assert.Equal(t, "app.mail_queue.stopped.app_error", errs[1].Id)
+	require.NotNil(t, errs[2])
+
+	finished := false
+	q.queue(&queuedMail{
+		send:   func() *model.AppError { return nil },
+		finish: func(err *model.AppError) { finished = err == nil },
+	}, MAIL_PRIORITY_LOW)
+	assert.True(t, finished, "emails queued after the queue was stopped should be sent immediately")
+}
+
+func waitForMailQueueLength(t *testing.T, q *mailQueue, length int) {
+	for start := time.Now(); q.length() != length; time.Sleep(time.Millisecond) {
+		require.True(t, time.Since(start) < 5*time.Second, "timed out waiting for the queue")
+	}
+}
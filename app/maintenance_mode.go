@@ -0,0 +1,212 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+const (
+	// Maintenance mode set from the CLI is picked up at least this often. Changes made through the API are sent
+	// to the rest of the cluster straight away.
+	MAINTENANCE_MODE_REFRESH_INTERVAL = 10 * time.Second
+
+	MAINTENANCE_MODE_REFRESH_TASK_NAME = "Refresh Maintenance Mode"
+)
+
+// GetMaintenanceMode returns the current maintenance mode, or nil if it isn't enabled.
+func (a *App) GetMaintenanceMode() *model.MaintenanceMode {
+	a.maintenanceModeLock.RLock()
+	defer a.maintenanceModeLock.RUnlock()
+
+	if a.maintenanceMode == nil {
+		return nil
+	}
+
+	mode := *a.maintenanceMode
+	return &mode
+}
+
+// CheckMaintenanceMode returns an error if maintenance mode is enabled and the session doesn't belong to a system
+// admin. Read-only maintenance mode doesn't keep anyone out.
+func (a *App) CheckMaintenanceMode(session model.Session) *model.AppError {
+	mode := a.GetMaintenanceMode()
+	if mode == nil || mode.ReadOnly || a.SessionHasPermissionTo(session, model.PERMISSION_MANAGE_SYSTEM) {
+		return nil
+	}
+
+	if mode.Message == "" {
+		return model.NewAppError("CheckMaintenanceMode", "app.maintenance_mode.enabled.app_error", nil, "", http.StatusServiceUnavailable)
+	}
+
+	return model.NewAppError("CheckMaintenanceMode", "app.maintenance_mode.enabled_with_message.app_error", map[string]interface{}{"Message": mode.Message}, "", http.StatusServiceUnavailable)
+}
+
+// CheckReadOnly returns an error with a status of Service Unavailable while the database is read-only, either
+// because read-only maintenance mode is enabled or because the master database is down, so that writes can be
+// refused before any work is done for them.
+func (a *App) CheckReadOnly() *model.AppError {
+	if a.Srv.Store.IsReadOnly() {
+		return store.NewReadOnlyError("CheckReadOnly")
+	}
+
+	return nil
+}
+
+// SetMaintenanceMode turns maintenance mode on or off for every server in the cluster. While it's on, only system
+// admins can use the API and everyone else is disconnected. While read-only maintenance mode is on, everyone can
+// still use the API, but anything that would change the database fails.
+func (a *App) SetMaintenanceMode(enabled bool, readOnly bool, message string) (*model.MaintenanceMode, *model.AppError) {
+	current := a.GetMaintenanceMode()
+
+	// The database has to be writable again to save the change
+	if current != nil && current.ReadOnly {
+		a.Srv.Store.SetReadOnly(false)
+	}
+
+	mode, err := a.saveMaintenanceMode(enabled, readOnly, message, current)
+	if err != nil {
+		if current != nil && current.ReadOnly {
+			a.Srv.Store.SetReadOnly(true)
+		}
+		return nil, err
+	}
+
+	a.updateMaintenanceMode(mode, true)
+
+	if mode == nil {
+		return &model.MaintenanceMode{}, nil
+	}
+
+	return mode, nil
+}
+
+// saveMaintenanceMode saves the maintenance mode for the rest of the cluster to load, returning nil if it's been
+// turned off.
+func (a *App) saveMaintenanceMode(enabled bool, readOnly bool, message string, current *model.MaintenanceMode) (*model.MaintenanceMode, *model.AppError) {
+	if !enabled {
+		if result := <-a.Srv.Store.System().PermanentDeleteByName(model.SYSTEM_MAINTENANCE_MODE); result.Err != nil {
+			return nil, result.Err
+		}
+
+		return nil, nil
+	}
+
+	mode := &model.MaintenanceMode{
+		Enabled:   true,
+		ReadOnly:  readOnly,
+		Message:   message,
+		StartedAt: model.GetMillis(),
+	}
+	if current != nil {
+		mode.StartedAt = current.StartedAt
+	}
+
+	if err := mode.IsValid(); err != nil {
+		return nil, err
+	}
+
+	if result := <-a.Srv.Store.System().SaveOrUpdate(&model.System{Name: model.SYSTEM_MAINTENANCE_MODE, Value: mode.ToJson()}); result.Err != nil {
+		return nil, result.Err
+	}
+
+	return mode, nil
+}
+
+func (a *App) loadMaintenanceMode() (*model.MaintenanceMode, *model.AppError) {
+	result := <-a.Srv.Store.System().Get()
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	value, ok := result.Data.(model.StringMap)[model.SYSTEM_MAINTENANCE_MODE]
+	if !ok {
+		return nil, nil
+	}
+
+	mode := model.MaintenanceModeFromJson(strings.NewReader(value))
+	if mode == nil || !mode.Enabled {
+		return nil, nil
+	}
+
+	return mode, nil
+}
+
+func (a *App) refreshMaintenanceMode() {
+	mode, err := a.loadMaintenanceMode()
+	if err != nil {
+		mlog.Error("Unable to load maintenance mode", mlog.String("error", err.Error()))
+		return
+	}
+
+	a.updateMaintenanceMode(mode, false)
+}
+
+func (a *App) startMaintenanceModeRefresh() {
+	a.refreshMaintenanceMode()
+
+	a.maintenanceModeTask = model.CreateRecurringTask(MAINTENANCE_MODE_REFRESH_TASK_NAME, a.refreshMaintenanceMode, MAINTENANCE_MODE_REFRESH_INTERVAL)
+}
+
+func (a *App) stopMaintenanceModeRefresh() {
+	if a.maintenanceModeTask != nil {
+		a.maintenanceModeTask.Cancel()
+		a.maintenanceModeTask = nil
+	}
+}
+
+// updateMaintenanceMode makes the maintenance mode the current one and tells this server's clients if it changed.
+// Clients that aren't system admins disconnect themselves once they've been told that it's enabled, unless it's
+// read-only, in which case the database is made read-only instead.
+func (a *App) updateMaintenanceMode(mode *model.MaintenanceMode, sendToCluster bool) {
+	a.maintenanceModeLock.Lock()
+	changed := maintenanceModeJson(a.maintenanceMode) != maintenanceModeJson(mode)
+	a.maintenanceMode = mode
+	a.maintenanceModeLock.Unlock()
+
+	a.Srv.Store.SetReadOnly(mode != nil && mode.ReadOnly)
+
+	if !changed {
+		return
+	}
+
+	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_MAINTENANCE_MODE, "", "", "", nil)
+	if mode != nil {
+		mlog.Info("Maintenance mode enabled", mlog.String("message", mode.Message))
+
+		message.Add("enabled", true)
+		message.Add("read_only", mode.ReadOnly)
+		message.Add("message", mode.Message)
+	} else {
+		mlog.Info("Maintenance mode disabled")
+
+		message.Add("enabled", false)
+	}
+
+	a.Go(func() {
+		a.PublishSkipClusterSend(message)
+	})
+
+	if sendToCluster && a.Cluster != nil {
+		a.Cluster.SendClusterMessage(&model.ClusterMessage{
+			Event:    model.CLUSTER_EVENT_UPDATE_MAINTENANCE_MODE,
+			SendType: model.CLUSTER_SEND_RELIABLE,
+			Data:     maintenanceModeJson(mode),
+		})
+	}
+}
+
+func maintenanceModeJson(mode *model.MaintenanceMode) string {
+	if mode == nil {
+		return ""
+	}
+
+	return mode.ToJson()
+}
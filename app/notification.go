@@ -10,7 +10,9 @@ import (
 	"net/http"
 	"net/url"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
@@ -86,10 +88,33 @@ func (a *App) SendNotifications(post *model.Post, team *model.Team, channel *mod
 		}
 
 	} else {
-		keywords := a.GetMentionKeywordsInChannel(profileMap, post.Type != model.POST_HEADER_CHANGE && post.Type != model.POST_PURPOSE_CHANGE)
+		allowChannelMentions := !channel.DisableChannelMentions && !a.userIsModeratedInChannel(post.UserId, channel.Id, model.CHANNEL_MODERATED_PERMISSION_USE_CHANNEL_MENTIONS)
+		keywords := a.GetMentionKeywordsInChannel(profileMap, allowChannelMentions && post.Type != model.POST_HEADER_CHANGE && post.Type != model.POST_PURPOSE_CHANGE)
 
 		m := GetExplicitMentions(post.Message, keywords)
 
+		// @here, @channel and @all do nothing when they're disabled in the channel or the channel's moderation
+		// settings don't let the sender use them
+		if !allowChannelMentions && (m.HereMentioned || m.ChannelMentioned || m.AllMentioned) {
+			if !post.IsSystemMessage() {
+				warning := "api.post.moderated_channel_mentions"
+				if channel.DisableChannelMentions {
+					warning = "api.post.disabled_channel_mentions"
+				}
+
+				a.SendEphemeralPost(
+					post.UserId,
+					&model.Post{
+						ChannelId: post.ChannelId,
+						Message:   utils.GetUserTranslations(sender.Locale)(warning),
+						CreateAt:  post.CreateAt + 1,
+					},
+				)
+			}
+
+			m.HereMentioned, m.ChannelMentioned, m.AllMentioned = false, false, false
+		}
+
 		// Add an implicit mention when a user is added to a channel
 		// even if the user has set 'username mentions' to false in account settings.
 		if post.Type == model.POST_ADD_TO_CHANNEL {
@@ -102,6 +127,30 @@ func (a *App) SendNotifications(post *model.Post, team *model.Team, channel *mod
 
 		mentionedUserIds, hereNotification, channelNotification, allNotification = m.MentionedUserIds, m.HereMentioned, m.ChannelMentioned, m.AllMentioned
 
+		// expand any mentioned custom groups to their members in the channel
+		if len(m.OtherPotentialMentions) > 0 && !post.IsSystemMessage() {
+			if groupMentionedUserIds, groupNames, err := a.getCustomGroupMentions(m.OtherPotentialMentions, profileMap); err != nil {
+				mlog.Error(fmt.Sprintf("Failed to get custom group mentions, post_id=%v err=%v", post.Id, err), mlog.String("post_id", post.Id))
+			} else if len(groupNames) > 0 {
+				if channel.DisableGroupMentions {
+					a.SendEphemeralPost(
+						post.UserId,
+						&model.Post{
+							ChannelId: post.ChannelId,
+							Message:   utils.GetUserTranslations(sender.Locale)("api.post.disabled_group_mentions"),
+							CreateAt:  post.CreateAt + 1,
+						},
+					)
+				} else {
+					for id := range groupMentionedUserIds {
+						mentionedUserIds[id] = true
+					}
+				}
+
+				m.OtherPotentialMentions = removeMentionsOfNames(m.OtherPotentialMentions, groupNames)
+			}
+		}
+
 		// get users that have comment thread mentions enabled
 		if len(post.RootId) > 0 && parentPostList != nil {
 			for _, threadPost := range parentPostList.Posts {
@@ -139,10 +188,20 @@ func (a *App) SendNotifications(post *model.Post, team *model.Team, channel *mod
 		}
 	}
 
+	if !post.IsSystemMessage() {
+		allActivityPushUserIds = a.removeUsersBlockingSender(post.UserId, mentionedUserIds, allActivityPushUserIds)
+	}
+
 	mentionedUsersList := make([]string, 0, len(mentionedUserIds))
 	for id := range mentionedUserIds {
 		mentionedUsersList = append(mentionedUsersList, id)
-		updateMentionChans = append(updateMentionChans, a.Srv.Store.Channel().IncrementMentionCount(post.ChannelId, id))
+		if len(post.RootId) > 0 && a.countsMentionsInThread(id, post.RootId) {
+			updateMentionChans = append(updateMentionChans, a.Srv.Store.Thread().IncrementMentionCount(id, post.RootId))
+		} else {
+			// A queued view of the channel would clear the mention when it's written
+			a.flushChannelViewsForUser(id)
+			updateMentionChans = append(updateMentionChans, a.Srv.Store.Channel().IncrementMentionCount(post.ChannelId, id))
+		}
 	}
 
 	senderName := ""
@@ -177,55 +236,14 @@ func (a *App) SendNotifications(post *model.Post, team *model.Team, channel *mod
 		senderUsername = sender.Username
 	}
 
-	if a.Config().EmailSettings.SendEmailNotifications {
-		for _, id := range mentionedUsersList {
-			if profileMap[id] == nil {
-				continue
-			}
-
-			userAllowsEmails := profileMap[id].NotifyProps[model.EMAIL_NOTIFY_PROP] != "false"
-			if channelEmail, ok := channelMemberNotifyPropsMap[id][model.EMAIL_NOTIFY_PROP]; ok {
-				if channelEmail != model.CHANNEL_NOTIFY_DEFAULT {
-					userAllowsEmails = channelEmail != "false"
-				}
-			}
-
-			// Remove the user as recipient when the user has muted the channel.
-			if channelMuted, ok := channelMemberNotifyPropsMap[id][model.MARK_UNREAD_NOTIFY_PROP]; ok {
-				if channelMuted == model.CHANNEL_MARK_UNREAD_MENTION {
-					mlog.Debug(fmt.Sprintf("Channel muted for user_id %v, channel_mute %v", id, channelMuted))
-					userAllowsEmails = false
-				}
-			}
-
-			//If email verification is required and user email is not verified don't send email.
-			if a.Config().EmailSettings.RequireEmailVerification && !profileMap[id].EmailVerified {
-				mlog.Error(fmt.Sprintf("Skipped sending notification email to %v, address not verified. [details: user_id=%v]", profileMap[id].Email, id))
-				continue
-			}
-
-			var status *model.Status
-			var err *model.AppError
-			if status, err = a.GetStatus(id); err != nil {
-				status = &model.Status{
-					UserId:         id,
-					Status:         model.STATUS_OFFLINE,
-					Manual:         false,
-					LastActivityAt: 0,
-					ActiveChannel:  "",
-				}
-			}
-
-			if userAllowsEmails && status.Status != model.STATUS_ONLINE && profileMap[id].DeleteAt == 0 {
-				a.sendNotificationEmail(post, profileMap[id], channel, team, channelName, senderName, sender)
-			}
-		}
-	}
-
 	T := utils.GetUserTranslations(sender.Locale)
 
+	// The limit is checked against the channel's member count, the same as when the author was asked to confirm
+	// the mention
+	memberCount := a.Srv.Store.Channel().GetMemberCountFromCache(channel.Id)
+
 	// If the channel has more than 1K users then @here is disabled
-	if hereNotification && int64(len(profileMap)) > *a.Config().TeamSettings.MaxNotificationsPerChannel {
+	if hereNotification && memberCount > *a.Config().TeamSettings.MaxNotificationsPerChannel {
 		hereNotification = false
 		a.SendEphemeralPost(
 			post.UserId,
@@ -238,7 +256,7 @@ func (a *App) SendNotifications(post *model.Post, team *model.Team, channel *mod
 	}
 
 	// If the channel has more than 1K users then @channel is disabled
-	if channelNotification && int64(len(profileMap)) > *a.Config().TeamSettings.MaxNotificationsPerChannel {
+	if channelNotification && memberCount > *a.Config().TeamSettings.MaxNotificationsPerChannel {
 		a.SendEphemeralPost(
 			post.UserId,
 			&model.Post{
@@ -250,7 +268,7 @@ func (a *App) SendNotifications(post *model.Post, team *model.Team, channel *mod
 	}
 
 	// If the channel has more than 1K users then @all is disabled
-	if allNotification && int64(len(profileMap)) > *a.Config().TeamSettings.MaxNotificationsPerChannel {
+	if allNotification && memberCount > *a.Config().TeamSettings.MaxNotificationsPerChannel {
 		a.SendEphemeralPost(
 			post.UserId,
 			&model.Post{
@@ -270,17 +288,180 @@ func (a *App) SendNotifications(post *model.Post, team *model.Team, channel *mod
 		}
 	}
 
+	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_POSTED, "", post.ChannelId, "", nil)
+	message.Add("post", a.PostWithProxyAddedToImageURLs(post).ToJson())
+	message.Add("channel_type", channel.Type)
+	message.Add("channel_display_name", channelName)
+	message.Add("channel_name", channel.Name)
+	message.Add("sender_name", senderUsername)
+	message.Add("team_id", team.Id)
+
+	if len(post.FileIds) != 0 && fchan != nil {
+		message.Add("otherFile", "true")
+
+		var infos []*model.FileInfo
+		if result := <-fchan; result.Err != nil {
+			mlog.Warn(fmt.Sprint("api.post.send_notifications.files.error FIXME: NOT FOUND IN TRANSLATIONS FILE", post.Id, result.Err), mlog.String("post_id", post.Id))
+		} else {
+			infos = result.Data.([]*model.FileInfo)
+		}
+
+		for _, info := range infos {
+			if info.IsImage() {
+				message.Add("image", "true")
+				break
+			}
+		}
+	}
+
+	if len(mentionedUsersList) != 0 {
+		message.Add("mentions", model.ArrayToJson(mentionedUsersList))
+	}
+
+	if *a.Config().ServiceSettings.ReliableWebsocketEvents {
+		// The outbox event for a post has the same id as it
+		a.publishWebSocketOutboxEvent(post.Id, message)
+	} else {
+		a.Publish(message)
+	}
+
+	notification := &postNotification{
+		post:                        post,
+		team:                        team,
+		channel:                     channel,
+		sender:                      sender,
+		profileMap:                  profileMap,
+		channelMemberNotifyPropsMap: channelMemberNotifyPropsMap,
+		mentionedUserIds:            mentionedUserIds,
+		mentionedUsersList:          mentionedUsersList,
+		allActivityPushUserIds:      allActivityPushUserIds,
+		senderName:                  senderName,
+		channelName:                 channelName,
+	}
+	a.queuePostJob(post, func(overloaded bool) *model.AppError {
+		a.sendPostNotifications(notification, overloaded)
+		return nil
+	})
+
+	return mentionedUsersList, nil
+}
+
+// postNotification holds everything needed to send the email and push notifications for a post.
+type postNotification struct {
+	post                        *model.Post
+	team                        *model.Team
+	channel                     *model.Channel
+	sender                      *model.User
+	profileMap                  map[string]*model.User
+	channelMemberNotifyPropsMap map[string]model.StringMap
+	mentionedUserIds            map[string]bool
+	mentionedUsersList          []string
+	allActivityPushUserIds      []string
+	senderName                  string
+	channelName                 string
+}
+
+// sendPostNotifications sends the email and push notifications for a post after its mention counts have been
+// updated. Notification emails that would be batched are skipped when overloaded is set.
+func (a *App) sendPostNotifications(n *postNotification, overloaded bool) {
+	post, team, channel, sender := n.post, n.team, n.channel, n.sender
+	profileMap, channelMemberNotifyPropsMap := n.profileMap, n.channelMemberNotifyPropsMap
+	mentionedUserIds, mentionedUsersList, allActivityPushUserIds := n.mentionedUserIds, n.mentionedUsersList, n.allActivityPushUserIds
+	senderName, channelName := n.senderName, n.channelName
+
+	traces := a.startNotificationTraces(post, profileMap)
+	defer a.saveNotificationTraces(traces)
+
+	for id, trace := range traces {
+		_, mentioned := mentionedUserIds[id]
+		trace.AddStep(model.NOTIFICATION_TRACE_TYPE_MENTION, model.NOTIFICATION_TRACE_CHECK_MENTIONED, strconv.FormatBool(mentioned), mentioned)
+
+		allActivity := false
+		for _, allActivityId := range allActivityPushUserIds {
+			allActivity = allActivity || allActivityId == id
+		}
+		trace.AddStep(model.NOTIFICATION_TRACE_TYPE_MENTION, model.NOTIFICATION_TRACE_CHECK_ALL_ACTIVITY, strconv.FormatBool(allActivity), allActivity)
+	}
+
+	if a.Config().EmailSettings.SendEmailNotifications {
+		for _, id := range mentionedUsersList {
+			if profileMap[id] == nil {
+				continue
+			}
+			trace := traces[id]
+
+			userAllowsEmails := profileMap[id].NotifyProps[model.EMAIL_NOTIFY_PROP] != "false"
+			if channelEmail, ok := channelMemberNotifyPropsMap[id][model.EMAIL_NOTIFY_PROP]; ok {
+				if channelEmail != model.CHANNEL_NOTIFY_DEFAULT {
+					userAllowsEmails = channelEmail != "false"
+				}
+			}
+			trace.AddStep(model.NOTIFICATION_TRACE_TYPE_EMAIL, model.NOTIFICATION_TRACE_CHECK_PREFERENCE, notificationPreferenceTraceValue(profileMap[id].NotifyProps[model.EMAIL_NOTIFY_PROP], channelMemberNotifyPropsMap[id][model.EMAIL_NOTIFY_PROP]), userAllowsEmails)
+
+			// Remove the user as recipient when the user has muted the channel.
+			if channelMuted, ok := channelMemberNotifyPropsMap[id][model.MARK_UNREAD_NOTIFY_PROP]; ok {
+				if channelMuted == model.CHANNEL_MARK_UNREAD_MENTION {
+					mlog.Debug(fmt.Sprintf("Channel muted for user_id %v, channel_mute %v", id, channelMuted))
+					userAllowsEmails = false
+					trace.AddStep(model.NOTIFICATION_TRACE_TYPE_EMAIL, model.NOTIFICATION_TRACE_CHECK_CHANNEL_MUTED, "true", false)
+				}
+			}
+
+			//If email verification is required and user email is not verified don't send email.
+			if a.Config().EmailSettings.RequireEmailVerification && !profileMap[id].EmailVerified {
+				mlog.Error(fmt.Sprintf("Skipped sending notification email to %v, address not verified. [details: user_id=%v]", profileMap[id].Email, id))
+				trace.AddStep(model.NOTIFICATION_TRACE_TYPE_EMAIL, model.NOTIFICATION_TRACE_CHECK_EMAIL_VERIFIED, "false", false)
+				continue
+			}
+
+			var status *model.Status
+			var err *model.AppError
+			if status, err = a.GetStatus(id); err != nil {
+				status = &model.Status{
+					UserId:         id,
+					Status:         model.STATUS_OFFLINE,
+					Manual:         false,
+					LastActivityAt: 0,
+					ActiveChannel:  "",
+				}
+			}
+
+			trace.AddStep(model.NOTIFICATION_TRACE_TYPE_EMAIL, model.NOTIFICATION_TRACE_CHECK_STATUS, status.Status, status.Status != model.STATUS_ONLINE)
+			if profileMap[id].DeleteAt != 0 {
+				trace.AddStep(model.NOTIFICATION_TRACE_TYPE_EMAIL, model.NOTIFICATION_TRACE_CHECK_DEACTIVATED, "true", false)
+			}
+
+			if userAllowsEmails && status.Status != model.STATUS_ONLINE && profileMap[id].DeleteAt == 0 {
+				if err := a.sendNotificationEmail(post, profileMap[id], channel, team, channelName, senderName, sender, overloaded, trace); err != nil {
+					mlog.Error(fmt.Sprintf("Failed to send notification email, post_id=%v user_id=%v err=%v", post.Id, id, err.Error()), mlog.String("post_id", post.Id))
+				}
+			}
+		}
+	} else {
+		for _, trace := range traces {
+			trace.AddStep(model.NOTIFICATION_TRACE_TYPE_EMAIL, model.NOTIFICATION_TRACE_CHECK_ENABLED, "false", false)
+		}
+	}
+
 	sendPushNotifications := false
+	pushDisabledReason := "false"
 	if *a.Config().EmailSettings.SendPushNotifications {
 		pushServer := *a.Config().EmailSettings.PushNotificationServer
 		if license := a.License(); pushServer == model.MHPNS && (license == nil || !*license.Features.MHPNS) {
 			mlog.Warn("api.post.send_notifications_and_forget.push_notification.mhpnsWarn FIXME: NOT FOUND IN TRANSLATIONS FILE")
 			sendPushNotifications = false
+			pushDisabledReason = "unlicensed"
 		} else {
 			sendPushNotifications = true
 		}
 	}
 
+	if !sendPushNotifications {
+		for _, trace := range traces {
+			trace.AddStep(model.NOTIFICATION_TRACE_TYPE_PUSH, model.NOTIFICATION_TRACE_CHECK_ENABLED, pushDisabledReason, false)
+		}
+	}
+
 	if sendPushNotifications {
 		for _, id := range mentionedUsersList {
 			if profileMap[id] == nil {
@@ -293,8 +474,9 @@ func (a *App) SendNotifications(post *model.Post, team *model.Team, channel *mod
 				status = &model.Status{UserId: id, Status: model.STATUS_OFFLINE, Manual: false, LastActivityAt: 0, ActiveChannel: ""}
 			}
 
+			tracePushNotificationChecks(traces[id], profileMap[id], channelMemberNotifyPropsMap[id], true, status, post)
 			if ShouldSendPushNotification(profileMap[id], channelMemberNotifyPropsMap[id], true, status, post) {
-				a.sendPushNotification(post, profileMap[id], channel, senderName, channelName, true)
+				a.sendPushNotification(post, profileMap[id], channel, senderName, channelName, true, traces[id])
 			}
 		}
 
@@ -310,48 +492,19 @@ func (a *App) SendNotifications(post *model.Post, team *model.Team, channel *mod
 					status = &model.Status{UserId: id, Status: model.STATUS_OFFLINE, Manual: false, LastActivityAt: 0, ActiveChannel: ""}
 				}
 
+				tracePushNotificationChecks(traces[id], profileMap[id], channelMemberNotifyPropsMap[id], false, status, post)
 				if ShouldSendPushNotification(profileMap[id], channelMemberNotifyPropsMap[id], false, status, post) {
-					a.sendPushNotification(post, profileMap[id], channel, senderName, channelName, false)
+					a.sendPushNotification(post, profileMap[id], channel, senderName, channelName, false, traces[id])
 				}
 			}
 		}
 	}
-
-	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_POSTED, "", post.ChannelId, "", nil)
-	message.Add("post", a.PostWithProxyAddedToImageURLs(post).ToJson())
-	message.Add("channel_type", channel.Type)
-	message.Add("channel_display_name", channelName)
-	message.Add("channel_name", channel.Name)
-	message.Add("sender_name", senderUsername)
-	message.Add("team_id", team.Id)
-
-	if len(post.FileIds) != 0 && fchan != nil {
-		message.Add("otherFile", "true")
-
-		var infos []*model.FileInfo
-		if result := <-fchan; result.Err != nil {
-			mlog.Warn(fmt.Sprint("api.post.send_notifications.files.error FIXME: NOT FOUND IN TRANSLATIONS FILE", post.Id, result.Err), mlog.String("post_id", post.Id))
-		} else {
-			infos = result.Data.([]*model.FileInfo)
-		}
-
-		for _, info := range infos {
-			if info.IsImage() {
-				message.Add("image", "true")
-				break
-			}
-		}
-	}
-
-	if len(mentionedUsersList) != 0 {
-		message.Add("mentions", model.ArrayToJson(mentionedUsersList))
-	}
-
-	a.Publish(message)
-	return mentionedUsersList, nil
 }
 
-func (a *App) sendNotificationEmail(post *model.Post, user *model.User, channel *model.Channel, team *model.Team, channelName string, senderName string, sender *model.User) *model.AppError {
+// sendNotificationEmail sends an email to the user about the post, or adds it to their next batch of notification
+// emails. Emails that would be batched are dropped when shedBatching is set. What was done is recorded in the trace
+// if it isn't nil.
+func (a *App) sendNotificationEmail(post *model.Post, user *model.User, channel *model.Channel, team *model.Team, channelName string, senderName string, sender *model.User, shedBatching bool, trace *model.NotificationTrace) *model.AppError {
 	if channel.IsGroupOrDirect() {
 		if result := <-a.Srv.Store.Team().GetTeamsByUserId(user.Id); result.Err != nil {
 			return result.Err
@@ -385,31 +538,47 @@ func (a *App) sendNotificationEmail(post *model.Post, user *model.User, channel
 			sendBatched = result.Data.(model.Preference).Value != model.PREFERENCE_EMAIL_INTERVAL_NO_BATCHING_SECONDS
 		}
 
+		if sendBatched && shedBatching {
+			mlog.Warn(fmt.Sprintf("Skipped batching notification email while overloaded, post_id=%v user_id=%v", post.Id, user.Id), mlog.String("post_id", post.Id))
+			trace.AddStep(model.NOTIFICATION_TRACE_TYPE_EMAIL, model.NOTIFICATION_TRACE_CHECK_EMAIL_BATCHING, "shed", false)
+			return nil
+		}
+
 		if sendBatched {
 			if err := a.AddNotificationEmailToBatch(user, post, team); err == nil {
+				trace.AddStep(model.NOTIFICATION_TRACE_TYPE_EMAIL, model.NOTIFICATION_TRACE_CHECK_EMAIL_BATCHING, "batched", true)
+				trace.SetOutcome(model.NOTIFICATION_TRACE_TYPE_EMAIL, model.NOTIFICATION_TRACE_OUTCOME_BATCHED)
 				return nil
 			}
 		}
 
+		trace.AddStep(model.NOTIFICATION_TRACE_TYPE_EMAIL, model.NOTIFICATION_TRACE_CHECK_EMAIL_BATCHING, "immediate", true)
+
 		// fall back to sending a single email if we can't batch it for some reason
 	}
 
 	translateFunc := utils.GetUserTranslations(user.Locale)
 
+	if post.IsSystemMessage() {
+		senderName = translateFunc("system.message.name")
+	}
+
 	emailNotificationContentsType := model.EMAIL_NOTIFICATION_CONTENTS_FULL
 	if license := a.License(); license != nil && *license.Features.EmailNotificationContents {
 		emailNotificationContentsType = *a.Config().EmailSettings.EmailNotificationContentsType
 	}
 
+	location := a.GetUserTimezoneLocation(user)
+
 	var subjectText string
 	if channel.Type == model.CHANNEL_DIRECT {
-		subjectText = getDirectMessageNotificationEmailSubject(post, translateFunc, a.Config().TeamSettings.SiteName, senderName)
+		subjectText = getDirectMessageNotificationEmailSubject(post, location, translateFunc, a.Config().TeamSettings.SiteName, senderName)
 	} else if channel.Type == model.CHANNEL_GROUP {
-		subjectText = getGroupMessageNotificationEmailSubject(post, translateFunc, a.Config().TeamSettings.SiteName, channelName, emailNotificationContentsType)
+		subjectText = getGroupMessageNotificationEmailSubject(post, location, translateFunc, a.Config().TeamSettings.SiteName, channelName, emailNotificationContentsType)
 	} else if *a.Config().EmailSettings.UseChannelInEmailNotifications {
-		subjectText = getNotificationEmailSubject(post, translateFunc, a.Config().TeamSettings.SiteName, team.DisplayName+" ("+channel.DisplayName+")")
+		subjectText = getNotificationEmailSubject(post, location, translateFunc, a.Config().TeamSettings.SiteName, team.DisplayName+" ("+channel.DisplayName+")")
 	} else {
-		subjectText = getNotificationEmailSubject(post, translateFunc, a.Config().TeamSettings.SiteName, team.DisplayName)
+		subjectText = getNotificationEmailSubject(post, location, translateFunc, a.Config().TeamSettings.SiteName, team.DisplayName)
 	}
 
 	teamURL := a.GetSiteURL() + "/" + team.Name
@@ -421,6 +590,8 @@ func (a *App) sendNotificationEmail(post *model.Post, user *model.User, channel
 		}
 	})
 
+	trace.SetOutcome(model.NOTIFICATION_TRACE_TYPE_EMAIL, model.NOTIFICATION_TRACE_OUTCOME_SENT)
+
 	if a.Metrics != nil {
 		a.Metrics.IncrementPostSentEmail()
 	}
@@ -431,8 +602,8 @@ func (a *App) sendNotificationEmail(post *model.Post, user *model.User, channel
 /**
  * Computes the subject line for direct notification email messages
  */
-func getDirectMessageNotificationEmailSubject(post *model.Post, translateFunc i18n.TranslateFunc, siteName string, senderName string) string {
-	t := getFormattedPostTime(post, translateFunc)
+func getDirectMessageNotificationEmailSubject(post *model.Post, location *time.Location, translateFunc i18n.TranslateFunc, siteName string, senderName string) string {
+	t := getFormattedPostTime(post, location, translateFunc)
 	var subjectParameters = map[string]interface{}{
 		"SiteName":          siteName,
 		"SenderDisplayName": senderName,
@@ -446,8 +617,8 @@ func getDirectMessageNotificationEmailSubject(post *model.Post, translateFunc i1
 /**
  * Computes the subject line for group, public, and private email messages
  */
-func getNotificationEmailSubject(post *model.Post, translateFunc i18n.TranslateFunc, siteName string, teamName string) string {
-	t := getFormattedPostTime(post, translateFunc)
+func getNotificationEmailSubject(post *model.Post, location *time.Location, translateFunc i18n.TranslateFunc, siteName string, teamName string) string {
+	t := getFormattedPostTime(post, location, translateFunc)
 	var subjectParameters = map[string]interface{}{
 		"SiteName": siteName,
 		"TeamName": teamName,
@@ -461,8 +632,8 @@ func getNotificationEmailSubject(post *model.Post, translateFunc i18n.TranslateF
 /**
  * Computes the subject line for group email messages
  */
-func getGroupMessageNotificationEmailSubject(post *model.Post, translateFunc i18n.TranslateFunc, siteName string, channelName string, emailNotificationContentsType string) string {
-	t := getFormattedPostTime(post, translateFunc)
+func getGroupMessageNotificationEmailSubject(post *model.Post, location *time.Location, translateFunc i18n.TranslateFunc, siteName string, channelName string, emailNotificationContentsType string) string {
+	t := getFormattedPostTime(post, location, translateFunc)
 	var subjectText string
 	if emailNotificationContentsType == model.EMAIL_NOTIFICATION_CONTENTS_FULL {
 		var subjectParameters = map[string]interface{}{
@@ -500,12 +671,12 @@ func (a *App) getNotificationEmailBody(recipient *model.User, post *model.Post,
 
 	bodyPage.Props["SiteURL"] = a.GetSiteURL()
 	if teamName != "select_team" {
-		bodyPage.Props["TeamLink"] = teamURL + "/pl/" + post.Id
+		bodyPage.Props["TeamLink"] = a.getNotificationPermalink(post, teamName, teamURL)
 	} else {
 		bodyPage.Props["TeamLink"] = teamURL
 	}
 
-	t := getFormattedPostTime(post, translateFunc)
+	t := getFormattedPostTime(post, a.GetUserTimezoneLocation(recipient), translateFunc)
 
 	var bodyText string
 	var info template.HTML
@@ -605,8 +776,11 @@ type formattedPostTime struct {
 	TimeZone string
 }
 
-func getFormattedPostTime(post *model.Post, translateFunc i18n.TranslateFunc) formattedPostTime {
-	tm := time.Unix(post.CreateAt/1000, 0)
+func getFormattedPostTime(post *model.Post, location *time.Location, translateFunc i18n.TranslateFunc) formattedPostTime {
+	return getFormattedTime(time.Unix(post.CreateAt/1000, 0).In(location), translateFunc)
+}
+
+func getFormattedTime(tm time.Time, translateFunc i18n.TranslateFunc) formattedPostTime {
 	zone, _ := tm.Zone()
 
 	return formattedPostTime{
@@ -621,8 +795,9 @@ func getFormattedPostTime(post *model.Post, translateFunc i18n.TranslateFunc) fo
 }
 
 func (a *App) GetMessageForNotification(post *model.Post, translateFunc i18n.TranslateFunc) string {
-	if len(strings.TrimSpace(post.Message)) != 0 || len(post.FileIds) == 0 {
-		return post.Message
+	message := RenderSystemMessage(post, translateFunc)
+	if len(strings.TrimSpace(message)) != 0 || len(post.FileIds) == 0 {
+		return message
 	}
 
 	// extract the filenames from their paths and determine what type of files are attached
@@ -655,17 +830,29 @@ func (a *App) GetMessageForNotification(post *model.Post, translateFunc i18n.Tra
 	}
 }
 
-func (a *App) sendPushNotification(post *model.Post, user *model.User, channel *model.Channel, senderName, channelName string, wasMentioned bool) *model.AppError {
+func (a *App) sendPushNotification(post *model.Post, user *model.User, channel *model.Channel, senderName, channelName string, wasMentioned bool, trace *model.NotificationTrace) *model.AppError {
 	sessions, err := a.getMobileAppSessions(user.Id)
 	if err != nil {
 		return err
 	}
 
+	trace.AddStep(model.NOTIFICATION_TRACE_TYPE_PUSH, model.NOTIFICATION_TRACE_CHECK_MOBILE_SESSIONS, strconv.Itoa(len(sessions)), len(sessions) > 0)
+	if len(sessions) > 0 {
+		trace.SetOutcome(model.NOTIFICATION_TRACE_TYPE_PUSH, model.NOTIFICATION_TRACE_OUTCOME_SENT)
+	}
+
+	userLocale := utils.GetUserTranslations(user.Locale)
+
+	if post.IsSystemMessage() {
+		senderName = userLocale("system.message.name")
+	}
+
 	if channel.Type == model.CHANNEL_DIRECT {
 		channelName = senderName
 	}
 
 	msg := model.PushNotification{}
+	a.flushChannelViewsForUser(user.Id)
 	if badge := <-a.Srv.Store.User().GetUnreadCount(user.Id); badge.Err != nil {
 		msg.Badge = 1
 		mlog.Error(fmt.Sprint("We could not get the unread message count for the user", user.Id, badge.Err), mlog.String("user_id", user.Id))
@@ -681,6 +868,16 @@ func (a *App) sendPushNotification(post *model.Post, user *model.User, channel *
 	msg.ChannelName = channel.Name
 	msg.SenderId = post.UserId
 
+	if *a.Config().ServiceSettings.EnableShortPermalinks {
+		if teamId := a.getPushNotificationTeamId(channel, user.Id); teamId != "" {
+			if link, err := a.GetShortPermalink(post, teamId); err != nil {
+				mlog.Warn(fmt.Sprintf("Failed to get a short permalink for a push notification: %v", err.Error()), mlog.String("post_id", post.Id))
+			} else {
+				msg.Permalink = link
+			}
+		}
+	}
+
 	if ou, ok := post.Props["override_username"].(string); ok {
 		msg.OverrideUsername = ou
 	}
@@ -693,10 +890,9 @@ func (a *App) sendPushNotification(post *model.Post, user *model.User, channel *
 		msg.FromWebhook = fw
 	}
 
-	userLocale := utils.GetUserTranslations(user.Locale)
 	hasFiles := post.FileIds != nil && len(post.FileIds) > 0
 
-	msg.Message, msg.Category = a.getPushNotificationMessage(post.Message, wasMentioned, hasFiles, senderName, channelName, channel.Type, userLocale)
+	msg.Message, msg.Category = a.getPushNotificationMessage(RenderSystemMessage(post, userLocale), wasMentioned, hasFiles, senderName, channelName, channel.Type, userLocale)
 
 	for _, session := range sessions {
 		tmpMessage := *model.PushNotificationFromJson(strings.NewReader(msg.ToJson()))
@@ -788,6 +984,7 @@ func (a *App) ClearPushNotification(userId string, channelId string) {
 		msg.Type = model.PUSH_TYPE_CLEAR
 		msg.ChannelId = channelId
 		msg.ContentAvailable = 0
+		a.flushChannelViewsForUser(userId)
 		if badge := <-a.Srv.Store.User().GetUnreadCount(userId); badge.Err != nil {
 			msg.Badge = 0
 			mlog.Error(fmt.Sprint("We could not get the unread message count for the user", userId, badge.Err), mlog.String("user_id", userId))
@@ -914,6 +1111,8 @@ type ExplicitMentions struct {
 
 // Given a message and a map mapping mention keywords to the users who use them, returns a map of mentioned
 // users and a slice of potential mention users not in the channel and whether or not @here was mentioned.
+// Keywords are matched regardless of case when they're in lower case, or exactly when they're prefixed with
+// model.MENTION_KEY_CASE_SENSITIVE_PREFIX, and keywords with spaces in them are matched as phrases.
 func GetExplicitMentions(message string, keywords map[string][]string) *ExplicitMentions {
 	ret := &ExplicitMentions{
 		MentionedUserIds: make(map[string]bool),
@@ -925,6 +1124,8 @@ func GetExplicitMentions(message string, keywords map[string][]string) *Explicit
 			ret.MentionedUserIds[id] = true
 		}
 	}
+
+	phrases := getMentionPhrases(keywords)
 	checkForMention := func(word string) bool {
 		isMention := false
 
@@ -952,9 +1153,21 @@ func GetExplicitMentions(message string, keywords map[string][]string) *Explicit
 			isMention = true
 		}
 
+		// Case-sensitive check for keys that are marked as such
+		if ids, match := keywords[model.MENTION_KEY_CASE_SENSITIVE_PREFIX+word]; match {
+			addMentionedUsers(ids)
+			isMention = true
+		}
+
 		return isMention
 	}
 	processText := func(text string) {
+		for _, phrase := range phrases {
+			if phrase.pattern.MatchString(text) {
+				addMentionedUsers(phrase.userIds)
+			}
+		}
+
 		for _, word := range strings.FieldsFunc(text, func(c rune) bool {
 			// Split on any whitespace or punctuation that can't be part of an at mention or emoji pattern
 			return !(c == ':' || c == '.' || c == '-' || c == '_' || c == '@' || unicode.IsLetter(c) || unicode.IsNumber(c))
@@ -1006,6 +1219,13 @@ func GetExplicitMentions(message string, keywords map[string][]string) *Explicit
 
 	buf := ""
 	markdown.Inspect(message, func(node interface{}) bool {
+		switch node.(type) {
+		case *markdown.SoftLineBreak, *markdown.HardLineBreak:
+			// keep the lines of a paragraph together so that a phrase can be split over them
+			buf += "\n"
+			return false
+		}
+
 		text, ok := node.(*markdown.Text)
 		if !ok {
 			processText(buf)
@@ -1020,6 +1240,52 @@ func GetExplicitMentions(message string, keywords map[string][]string) *Explicit
 	return ret
 }
 
+type mentionPhrase struct {
+	pattern *regexp.Regexp
+	userIds []string
+}
+
+// getMentionPhrases returns the keywords that are made of several words, which can't be matched one word at a
+// time, as patterns that match the words separated by any whitespace and not surrounded by other letters.
+func getMentionPhrases(keywords map[string][]string) []*mentionPhrase {
+	phrases := []*mentionPhrase{}
+	for keyword, ids := range keywords {
+		caseSensitive := strings.HasPrefix(keyword, model.MENTION_KEY_CASE_SENSITIVE_PREFIX)
+		words := strings.Fields(strings.TrimPrefix(keyword, model.MENTION_KEY_CASE_SENSITIVE_PREFIX))
+		if len(words) < 2 {
+			continue
+		}
+
+		for i, word := range words {
+			words[i] = regexp.QuoteMeta(word)
+		}
+
+		pattern := `(^|[^\pL\pN_@])` + strings.Join(words, `\s+`) + `($|[^\pL\pN_])`
+		if !caseSensitive {
+			pattern = "(?i)" + pattern
+		}
+
+		phrases = append(phrases, &mentionPhrase{
+			pattern: regexp.MustCompile(pattern),
+			userIds: ids,
+		})
+	}
+
+	return phrases
+}
+
+// removeMentionsOfNames returns the potential mentions that don't match any of the given lower case names.
+func removeMentionsOfNames(potentialMentions []string, names []string) []string {
+	remaining := []string{}
+	for _, mention := range potentialMentions {
+		if !utils.StringInSlice(strings.ToLower(mention), names) {
+			remaining = append(remaining, mention)
+		}
+	}
+
+	return remaining
+}
+
 // Given a map of user IDs to profiles, returns a list of mention
 // keywords for all users in the channel.
 func (a *App) GetMentionKeywordsInChannel(profiles map[string]*model.User, lookForSpecialMentions bool) map[string][]string {
@@ -1031,10 +1297,13 @@ func (a *App) GetMentionKeywordsInChannel(profiles map[string]*model.User, lookF
 
 		if len(profile.NotifyProps["mention_keys"]) > 0 {
 			// Add all the user's mention keys
-			splitKeys := strings.Split(profile.NotifyProps["mention_keys"], ",")
-			for _, k := range splitKeys {
-				// note that these are made lower case so that we can do a case insensitive check for them
-				key := strings.ToLower(k)
+			for _, k := range model.ParseMentionKeys(profile.NotifyProps["mention_keys"]) {
+				// note that these are made lower case so that we can do a case insensitive check for them,
+				// while the case sensitive ones are marked so that they can't be matched by their lower case
+				key := strings.ToLower(k.Key)
+				if k.CaseSensitive {
+					key = model.MENTION_KEY_CASE_SENSITIVE_PREFIX + k.Key
+				}
 				keywords[key] = append(keywords[key], id)
 			}
 		}
@@ -6,8 +6,10 @@ package app
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/utils"
@@ -464,6 +466,67 @@ func TestGetExplicitMentions(t *testing.T) {
 				},
 			},
 		},
+		"Phrase": {
+			Message:  "the Deploy failed.",
+			Keywords: map[string][]string{"deploy failed": {id1}},
+			Expected: &ExplicitMentions{
+				MentionedUserIds: map[string]bool{
+					id1: true,
+				},
+			},
+		},
+		"Phrase split over whitespace": {
+			Message:  "deploy  \nfailed",
+			Keywords: map[string][]string{"deploy failed": {id1}},
+			Expected: &ExplicitMentions{
+				MentionedUserIds: map[string]bool{
+					id1: true,
+				},
+			},
+		},
+		"Phrase that's part of other words": {
+			Message:  "it didn't redeploy failed jobs, the deploy failedover",
+			Keywords: map[string][]string{"deploy failed": {id1}},
+			Expected: &ExplicitMentions{},
+		},
+		"Phrase with words in between": {
+			Message:  "deploy has failed",
+			Keywords: map[string][]string{"deploy failed": {id1}},
+			Expected: &ExplicitMentions{},
+		},
+		"Phrase in a code block": {
+			Message:  "```\ndeploy failed\n```",
+			Keywords: map[string][]string{"deploy failed": {id1}},
+			Expected: &ExplicitMentions{},
+		},
+		"Case sensitive keyword": {
+			Message:  "Bob and bob",
+			Keywords: map[string][]string{"=Bob": {id1}, "=bob": {id2}, "=BOB": {id3}},
+			Expected: &ExplicitMentions{
+				MentionedUserIds: map[string]bool{
+					id1: true,
+					id2: true,
+				},
+			},
+		},
+		"Case sensitive phrase": {
+			Message:  "Deploy Failed, deploy failed",
+			Keywords: map[string][]string{"=Deploy Failed": {id1}, "=DEPLOY FAILED": {id2}},
+			Expected: &ExplicitMentions{
+				MentionedUserIds: map[string]bool{
+					id1: true,
+				},
+			},
+		},
+		"Keyword and at mention of the same user": {
+			Message:  "@user, the deploy failed",
+			Keywords: map[string][]string{"@user": {id1}, "deploy failed": {id1}},
+			Expected: &ExplicitMentions{
+				MentionedUserIds: map[string]bool{
+					id1: true,
+				},
+			},
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			m := GetExplicitMentions(tc.Message, tc.Keywords)
@@ -564,6 +627,28 @@ func TestGetMentionKeywords(t *testing.T) {
 		t.Fatal("should've returned mention key of mention")
 	}
 
+	// user with phrase and case sensitive mention keys
+	user1 = &model.User{
+		Id:        model.NewId(),
+		FirstName: "First",
+		Username:  "User",
+		NotifyProps: map[string]string{
+			"mention_keys": `"Deploy Failed",=Bob,="Build, Broken"`,
+		},
+	}
+
+	profiles = map[string]*model.User{user1.Id: user1}
+	mentions = th.App.GetMentionKeywordsInChannel(profiles, true)
+	if len(mentions) != 3 {
+		t.Fatal("should've returned three mention keywords")
+	} else if ids, ok := mentions["deploy failed"]; !ok || ids[0] != user1.Id {
+		t.Fatal("should've returned mention key of deploy failed")
+	} else if ids, ok := mentions["=Bob"]; !ok || ids[0] != user1.Id {
+		t.Fatal("should've returned case sensitive mention key of Bob")
+	} else if ids, ok := mentions["=Build, Broken"]; !ok || ids[0] != user1.Id {
+		t.Fatal("should've returned case sensitive mention key of Build, Broken")
+	}
+
 	// user with first name mention enabled
 	user2 := &model.User{
 		Id:        model.NewId(),
@@ -1048,7 +1133,7 @@ func TestGetDirectMessageNotificationEmailSubject(t *testing.T) {
 		CreateAt: 1501804801000,
 	}
 	translateFunc := utils.GetUserTranslations("en")
-	subject := getDirectMessageNotificationEmailSubject(post, translateFunc, "http://localhost:8065", "sender")
+	subject := getDirectMessageNotificationEmailSubject(post, time.UTC, translateFunc, "http://localhost:8065", "sender")
 	if !strings.HasPrefix(subject, expectedPrefix) {
 		t.Fatal("Expected subject line prefix '" + expectedPrefix + "', got " + subject)
 	}
@@ -1064,7 +1149,7 @@ func TestGetGroupMessageNotificationEmailSubjectFull(t *testing.T) {
 	}
 	translateFunc := utils.GetUserTranslations("en")
 	emailNotificationContentsType := model.EMAIL_NOTIFICATION_CONTENTS_FULL
-	subject := getGroupMessageNotificationEmailSubject(post, translateFunc, "http://localhost:8065", "sender", emailNotificationContentsType)
+	subject := getGroupMessageNotificationEmailSubject(post, time.UTC, translateFunc, "http://localhost:8065", "sender", emailNotificationContentsType)
 	if !strings.HasPrefix(subject, expectedPrefix) {
 		t.Fatal("Expected subject line prefix '" + expectedPrefix + "', got " + subject)
 	}
@@ -1080,7 +1165,7 @@ func TestGetGroupMessageNotificationEmailSubjectGeneric(t *testing.T) {
 	}
 	translateFunc := utils.GetUserTranslations("en")
 	emailNotificationContentsType := model.EMAIL_NOTIFICATION_CONTENTS_GENERIC
-	subject := getGroupMessageNotificationEmailSubject(post, translateFunc, "http://localhost:8065", "sender", emailNotificationContentsType)
+	subject := getGroupMessageNotificationEmailSubject(post, time.UTC, translateFunc, "http://localhost:8065", "sender", emailNotificationContentsType)
 	if !strings.HasPrefix(subject, expectedPrefix) {
 		t.Fatal("Expected subject line prefix '" + expectedPrefix + "', got " + subject)
 	}
@@ -1095,7 +1180,7 @@ func TestGetNotificationEmailSubject(t *testing.T) {
 		CreateAt: 1501804801000,
 	}
 	translateFunc := utils.GetUserTranslations("en")
-	subject := getNotificationEmailSubject(post, translateFunc, "http://localhost:8065", "team")
+	subject := getNotificationEmailSubject(post, time.UTC, translateFunc, "http://localhost:8065", "team")
 	if !strings.HasPrefix(subject, expectedPrefix) {
 		t.Fatal("Expected subject line prefix '" + expectedPrefix + "', got " + subject)
 	}
@@ -1138,6 +1223,74 @@ func TestGetNotificationEmailBodyFullNotificationPublicChannel(t *testing.T) {
 	}
 }
 
+func TestGetFormattedPostTime(t *testing.T) {
+	post := &model.Post{
+		CreateAt: 1501804801000,
+	}
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.Nil(t, err)
+	bogota, err := time.LoadLocation("America/Bogota")
+	require.Nil(t, err)
+
+	formatted := getFormattedPostTime(post, tokyo, utils.GetUserTranslations("fr"))
+	assert.Equal(t, "4", formatted.Day)
+	assert.Equal(t, "Août", formatted.Month)
+	assert.Equal(t, "09", formatted.Hour)
+	assert.Equal(t, "00", formatted.Minute)
+	assert.Equal(t, "JST", formatted.TimeZone)
+
+	formatted = getFormattedPostTime(post, bogota, utils.GetUserTranslations("en"))
+	assert.Equal(t, "3", formatted.Day)
+	assert.Equal(t, "August", formatted.Month)
+	assert.Equal(t, "19", formatted.Hour)
+	assert.Equal(t, "2017", formatted.Year)
+}
+
+func TestGetNotificationEmailBodyInRecipientTimezone(t *testing.T) {
+	th := Setup()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.ServiceSettings.DefaultTimezone = "Asia/Tokyo"
+	})
+
+	post := &model.Post{
+		Message:  "This is the message",
+		CreateAt: 1501804801000,
+	}
+	channel := &model.Channel{
+		DisplayName: "ChannelName",
+		Type:        model.CHANNEL_DIRECT,
+	}
+	translateFunc := utils.GetUserTranslations("en")
+
+	for name, tc := range map[string]struct {
+		Timezone model.StringMap
+		Expected string
+	}{
+		"UTC+9": {
+			Timezone: model.StringMap{"useAutomaticTimezone": "true", "automaticTimezone": "Asia/Tokyo", "manualTimezone": ""},
+			Expected: "@sender - 09:00 JST, August 4",
+		},
+		"UTC-5": {
+			Timezone: model.StringMap{"useAutomaticTimezone": "false", "automaticTimezone": "Asia/Tokyo", "manualTimezone": "America/Bogota"},
+			Expected: "@sender - 19:00 -05, August 3",
+		},
+		"no timezone chosen": {
+			Timezone: model.StringMap{"useAutomaticTimezone": "false", "automaticTimezone": "America/Bogota", "manualTimezone": ""},
+			Expected: "@sender - 09:00 JST, August 4",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			recipient := &model.User{Timezone: tc.Timezone}
+
+			body := th.App.getNotificationEmailBody(recipient, post, channel, "ChannelName", "sender", "team", "http://localhost:8065/team", model.EMAIL_NOTIFICATION_CONTENTS_FULL, translateFunc)
+			assert.Contains(t, body, tc.Expected)
+		})
+	}
+}
+
 func TestGetNotificationEmailBodyFullNotificationGroupChannel(t *testing.T) {
 	th := Setup()
 	defer th.TearDown()
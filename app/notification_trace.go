@@ -0,0 +1,158 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const (
+	NOTIFICATION_TRACE_CACHE_SIZE = 10000
+
+	// Traces are kept for a day after they're recorded, or until newer ones push them out of the cache.
+	NOTIFICATION_TRACE_EXPIRY_SECONDS = 24 * 60 * 60
+)
+
+// GetNotificationTraceStatus returns when tracing the user's notifications stops, if it's turned on.
+func (a *App) GetNotificationTraceStatus(userId string) *model.NotificationTraceStatus {
+	a.notificationTracesLock.RLock()
+	defer a.notificationTracesLock.RUnlock()
+
+	status := &model.NotificationTraceStatus{UserId: userId}
+	if expiresAt := a.notificationTraces[userId]; expiresAt > model.GetMillis() {
+		status.ExpiresAt = expiresAt
+	}
+
+	return status
+}
+
+// SetNotificationTrace turns tracing the user's notifications on for the given number of seconds, or off if it's 0.
+// While it's on, the reasons that the user was or wasn't notified about each post are recorded by the server that sent
+// the notifications.
+func (a *App) SetNotificationTrace(userId string, ttlSeconds int64) (*model.NotificationTraceStatus, *model.AppError) {
+	status := &model.NotificationTraceStatus{UserId: userId, TTLSeconds: ttlSeconds}
+	if err := status.IsValid(); err != nil {
+		return nil, err
+	}
+
+	if ttlSeconds > 0 {
+		status.ExpiresAt = model.GetMillis() + ttlSeconds*1000
+	}
+
+	a.updateNotificationTrace(status, true)
+
+	return a.GetNotificationTraceStatus(userId), nil
+}
+
+// GetNotificationTrace returns the reasons that the user was or wasn't notified about the post.
+func (a *App) GetNotificationTrace(userId string, postId string) (*model.NotificationTrace, *model.AppError) {
+	if trace, ok := a.notificationTraceCache.Get(notificationTraceKey(userId, postId)); ok {
+		return trace.(*model.NotificationTrace), nil
+	}
+
+	return nil, model.NewAppError("GetNotificationTrace", "app.notification_trace.get.not_found.app_error", nil, fmt.Sprintf("user_id=%v, post_id=%v", userId, postId), http.StatusNotFound)
+}
+
+func (a *App) updateNotificationTrace(status *model.NotificationTraceStatus, sendToCluster bool) {
+	a.notificationTracesLock.Lock()
+	now := model.GetMillis()
+	for userId, expiresAt := range a.notificationTraces {
+		if expiresAt <= now {
+			delete(a.notificationTraces, userId)
+		}
+	}
+	if status.ExpiresAt > now {
+		a.notificationTraces[status.UserId] = status.ExpiresAt
+	} else {
+		delete(a.notificationTraces, status.UserId)
+	}
+	a.notificationTracesLock.Unlock()
+
+	if sendToCluster && a.Cluster != nil {
+		a.Cluster.SendClusterMessage(&model.ClusterMessage{
+			Event:    model.CLUSTER_EVENT_UPDATE_NOTIFICATION_TRACE,
+			SendType: model.CLUSTER_SEND_RELIABLE,
+			Data:     status.ToJson(),
+		})
+	}
+}
+
+// startNotificationTraces returns a new trace for each of the users in the channel whose notifications are being
+// traced. It returns nil if there aren't any, and looking up a user in a nil map returns a nil trace, which can
+// still be recorded into.
+func (a *App) startNotificationTraces(post *model.Post, profileMap map[string]*model.User) map[string]*model.NotificationTrace {
+	a.notificationTracesLock.RLock()
+	defer a.notificationTracesLock.RUnlock()
+
+	if len(a.notificationTraces) == 0 {
+		return nil
+	}
+
+	var traces map[string]*model.NotificationTrace
+	now := model.GetMillis()
+	for userId, expiresAt := range a.notificationTraces {
+		if _, ok := profileMap[userId]; !ok || expiresAt <= now {
+			continue
+		}
+
+		if traces == nil {
+			traces = make(map[string]*model.NotificationTrace)
+		}
+		traces[userId] = model.NewNotificationTrace(userId, post)
+	}
+
+	return traces
+}
+
+func (a *App) saveNotificationTraces(traces map[string]*model.NotificationTrace) {
+	for userId, trace := range traces {
+		a.notificationTraceCache.AddWithExpiresInSecs(notificationTraceKey(userId, trace.PostId), trace, NOTIFICATION_TRACE_EXPIRY_SECONDS)
+	}
+}
+
+// tracePushNotificationChecks records the checks made by ShouldSendPushNotification, up to the first one that stops
+// the notification from being sent.
+func tracePushNotificationChecks(trace *model.NotificationTrace, user *model.User, channelNotifyProps model.StringMap, wasMentioned bool, status *model.Status, post *model.Post) {
+	if trace == nil {
+		return
+	}
+
+	if channelNotifyProps[model.MARK_UNREAD_NOTIFY_PROP] == model.CHANNEL_MARK_UNREAD_MENTION {
+		trace.AddStep(model.NOTIFICATION_TRACE_TYPE_PUSH, model.NOTIFICATION_TRACE_CHECK_CHANNEL_MUTED, "true", false)
+		return
+	}
+
+	if post.IsSystemMessage() {
+		trace.AddStep(model.NOTIFICATION_TRACE_TYPE_PUSH, model.NOTIFICATION_TRACE_CHECK_SYSTEM_MESSAGE, "true", false)
+		return
+	}
+
+	preference := notificationPreferenceTraceValue(user.NotifyProps[model.PUSH_NOTIFY_PROP], channelNotifyProps[model.PUSH_NOTIFY_PROP])
+	allowed := DoesNotifyPropsAllowPushNotification(user, channelNotifyProps, post, wasMentioned)
+	trace.AddStep(model.NOTIFICATION_TRACE_TYPE_PUSH, model.NOTIFICATION_TRACE_CHECK_PREFERENCE, preference, allowed)
+	if !allowed {
+		return
+	}
+
+	statusValue := fmt.Sprintf("status=%v push_status=%v", status.Status, user.NotifyProps["push_status"])
+	if status.ActiveChannel == post.ChannelId {
+		statusValue += " viewing_channel=true"
+	}
+	trace.AddStep(model.NOTIFICATION_TRACE_TYPE_PUSH, model.NOTIFICATION_TRACE_CHECK_STATUS, statusValue, DoesStatusAllowPushNotification(user.NotifyProps, status, post.ChannelId))
+}
+
+func notificationPreferenceTraceValue(userPreference string, channelPreference string) string {
+	if channelPreference == "" {
+		channelPreference = model.CHANNEL_NOTIFY_DEFAULT
+	}
+
+	return fmt.Sprintf("user=%v channel=%v", userPreference, channelPreference)
+}
+
+func notificationTraceKey(userId string, postId string) string {
+	return userId + ":" + postId
+}
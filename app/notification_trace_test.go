@@ -0,0 +1,175 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestNotificationTrace(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		cfg.EmailSettings.SendEmailNotifications = true
+		*cfg.EmailSettings.SendPushNotifications = true
+		*cfg.EmailSettings.PushNotificationServer = "http://localhost:8065"
+		*cfg.EmailSettings.EnableEmailBatching = false
+	})
+
+	th.App.AddUserToChannel(th.BasicUser2, th.BasicChannel)
+
+	post := func(message string) *model.Post {
+		post, err := th.App.CreatePostMissingChannel(&model.Post{
+			UserId:    th.BasicUser.Id,
+			ChannelId: th.BasicChannel.Id,
+			Message:   message,
+		}, false)
+		require.Nil(t, err)
+		return post
+	}
+
+	// Notifications are sent after the post is created, so the trace might not have been recorded yet
+	getTrace := func(post *model.Post) *model.NotificationTrace {
+		var trace *model.NotificationTrace
+		var err *model.AppError
+		for i := 0; i < 20; i++ {
+			if trace, err = th.App.GetNotificationTrace(th.BasicUser2.Id, post.Id); err == nil {
+				return trace
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		require.Nil(t, err)
+		return trace
+	}
+
+	t.Run("not traced", func(t *testing.T) {
+		p := post("@" + th.BasicUser2.Username + " not traced")
+		time.Sleep(200 * time.Millisecond)
+
+		_, err := th.App.GetNotificationTrace(th.BasicUser2.Id, p.Id)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.notification_trace.get.not_found.app_error", err.Id)
+	})
+
+	status, err := th.App.SetNotificationTrace(th.BasicUser2.Id, 60)
+	require.Nil(t, err)
+	assert.True(t, status.ExpiresAt > model.GetMillis())
+	defer th.App.SetNotificationTrace(th.BasicUser2.Id, 0)
+
+	t.Run("not mentioned", func(t *testing.T) {
+		trace := getTrace(post("nothing to see here"))
+
+		step := trace.FindStep(model.NOTIFICATION_TRACE_TYPE_MENTION, model.NOTIFICATION_TRACE_CHECK_MENTIONED)
+		require.NotNil(t, step)
+		assert.False(t, step.Allowed)
+		assert.Equal(t, model.NOTIFICATION_TRACE_OUTCOME_NOT_SENT, trace.Email)
+		assert.Equal(t, model.NOTIFICATION_TRACE_OUTCOME_NOT_SENT, trace.Push)
+		assert.NotContains(t, trace.ToJson(), "nothing to see here", "the trace shouldn't include the message")
+	})
+
+	t.Run("mentioned while offline", func(t *testing.T) {
+		th.App.SetStatusOffline(th.BasicUser2.Id, true)
+
+		trace := getTrace(post("@" + th.BasicUser2.Username + " are you there?"))
+
+		step := trace.FindStep(model.NOTIFICATION_TRACE_TYPE_MENTION, model.NOTIFICATION_TRACE_CHECK_MENTIONED)
+		require.NotNil(t, step)
+		assert.True(t, step.Allowed)
+
+		step = trace.FindStep(model.NOTIFICATION_TRACE_TYPE_EMAIL, model.NOTIFICATION_TRACE_CHECK_EMAIL_BATCHING)
+		require.NotNil(t, step)
+		assert.Equal(t, "immediate", step.Value)
+		assert.Equal(t, model.NOTIFICATION_TRACE_OUTCOME_SENT, trace.Email)
+
+		step = trace.FindStep(model.NOTIFICATION_TRACE_TYPE_PUSH, model.NOTIFICATION_TRACE_CHECK_MOBILE_SESSIONS)
+		require.NotNil(t, step, "push notifications should have been considered")
+		assert.Equal(t, "0", step.Value)
+		assert.False(t, step.Allowed)
+		assert.Equal(t, model.NOTIFICATION_TRACE_OUTCOME_NOT_SENT, trace.Push)
+	})
+
+	t.Run("do not disturb", func(t *testing.T) {
+		th.App.SetStatusDoNotDisturb(th.BasicUser2.Id)
+		defer th.App.SetStatusOffline(th.BasicUser2.Id, true)
+
+		trace := getTrace(post("@" + th.BasicUser2.Username + " urgent"))
+
+		step := trace.FindStep(model.NOTIFICATION_TRACE_TYPE_PUSH, model.NOTIFICATION_TRACE_CHECK_STATUS)
+		require.NotNil(t, step)
+		assert.Contains(t, step.Value, model.STATUS_DND)
+		assert.False(t, step.Allowed)
+		assert.Nil(t, trace.FindStep(model.NOTIFICATION_TRACE_TYPE_PUSH, model.NOTIFICATION_TRACE_CHECK_MOBILE_SESSIONS))
+	})
+
+	t.Run("email preference", func(t *testing.T) {
+		user, err := th.App.GetUser(th.BasicUser2.Id)
+		require.Nil(t, err)
+
+		props := model.CopyStringMap(user.NotifyProps)
+		props[model.EMAIL_NOTIFY_PROP] = "false"
+		_, err = th.App.UpdateUserNotifyProps(th.BasicUser2.Id, props)
+		require.Nil(t, err)
+		defer th.App.UpdateUserNotifyProps(th.BasicUser2.Id, user.NotifyProps)
+
+		trace := getTrace(post("@" + th.BasicUser2.Username + " check your email"))
+
+		step := trace.FindStep(model.NOTIFICATION_TRACE_TYPE_EMAIL, model.NOTIFICATION_TRACE_CHECK_PREFERENCE)
+		require.NotNil(t, step)
+		assert.False(t, step.Allowed)
+		assert.Equal(t, model.NOTIFICATION_TRACE_OUTCOME_NOT_SENT, trace.Email)
+	})
+
+	t.Run("muted channel", func(t *testing.T) {
+		_, err := th.App.UpdateChannelMemberNotifyProps(map[string]string{model.MARK_UNREAD_NOTIFY_PROP: model.CHANNEL_MARK_UNREAD_MENTION}, th.BasicChannel.Id, th.BasicUser2.Id)
+		require.Nil(t, err)
+		defer th.App.UpdateChannelMemberNotifyProps(map[string]string{model.MARK_UNREAD_NOTIFY_PROP: model.CHANNEL_MARK_UNREAD_ALL}, th.BasicChannel.Id, th.BasicUser2.Id)
+
+		trace := getTrace(post("@" + th.BasicUser2.Username + " in a muted channel"))
+
+		for _, traceType := range []string{model.NOTIFICATION_TRACE_TYPE_EMAIL, model.NOTIFICATION_TRACE_TYPE_PUSH} {
+			step := trace.FindStep(traceType, model.NOTIFICATION_TRACE_CHECK_CHANNEL_MUTED)
+			require.NotNil(t, step, traceType)
+			assert.False(t, step.Allowed, traceType)
+		}
+		assert.Equal(t, model.NOTIFICATION_TRACE_OUTCOME_NOT_SENT, trace.Email)
+	})
+
+	t.Run("notifications disabled", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) {
+			cfg.EmailSettings.SendEmailNotifications = false
+			*cfg.EmailSettings.SendPushNotifications = false
+		})
+
+		trace := getTrace(post("@" + th.BasicUser2.Username + " nothing will be sent"))
+
+		for _, traceType := range []string{model.NOTIFICATION_TRACE_TYPE_EMAIL, model.NOTIFICATION_TRACE_TYPE_PUSH} {
+			step := trace.FindStep(traceType, model.NOTIFICATION_TRACE_CHECK_ENABLED)
+			require.NotNil(t, step, traceType)
+			assert.False(t, step.Allowed, traceType)
+		}
+	})
+
+	t.Run("tracing turned off", func(t *testing.T) {
+		status, err := th.App.SetNotificationTrace(th.BasicUser2.Id, 0)
+		require.Nil(t, err)
+		assert.Zero(t, status.ExpiresAt)
+
+		p := post("@" + th.BasicUser2.Username + " no longer traced")
+		time.Sleep(200 * time.Millisecond)
+
+		_, err = th.App.GetNotificationTrace(th.BasicUser2.Id, p.Id)
+		assert.NotNil(t, err)
+	})
+
+	_, err = th.App.SetNotificationTrace(th.BasicUser2.Id, model.NOTIFICATION_TRACE_MAX_TTL_SECONDS+1)
+	require.NotNil(t, err)
+	assert.Equal(t, "model.notification_trace.is_valid.ttl.app_error", err.Id)
+}
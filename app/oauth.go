@@ -5,6 +5,7 @@ package app
 
 import (
 	"bytes"
+	"crypto/subtle"
 	b64 "encoding/base64"
 	"fmt"
 	"io"
@@ -165,7 +166,7 @@ func (a *App) GetOAuthAccessToken(clientId, grantType, redirectUri, code, secret
 		oauthApp = result.Data.(*model.OAuthApp)
 	}
 
-	if oauthApp.ClientSecret != secret {
+	if subtle.ConstantTimeCompare([]byte(oauthApp.ClientSecret), []byte(secret)) != 1 {
 		return nil, model.NewAppError("GetOAuthAccessToken", "api.oauth.get_access_token.credentials.app_error", nil, "", http.StatusForbidden)
 	}
 
@@ -201,7 +202,7 @@ func (a *App) GetOAuthAccessToken(clientId, grantType, redirectUri, code, secret
 		} else if result.Data != nil {
 			accessData := result.Data.(*model.AccessData)
 			if accessData.IsExpired() {
-				if access, err := a.newSessionUpdateToken(oauthApp.Name, accessData, user); err != nil {
+				if access, err := a.newSessionUpdateToken(oauthApp, accessData, user); err != nil {
 					return nil, err
 				} else {
 					accessRsp = access
@@ -218,7 +219,7 @@ func (a *App) GetOAuthAccessToken(clientId, grantType, redirectUri, code, secret
 		} else {
 			// create a new session and return new access token
 			var session *model.Session
-			if result, err := a.newSession(oauthApp.Name, user); err != nil {
+			if result, err := a.newSession(oauthApp, user); err != nil {
 				return nil, err
 			} else {
 				session = result
@@ -235,7 +236,7 @@ func (a *App) GetOAuthAccessToken(clientId, grantType, redirectUri, code, secret
 				AccessToken:  session.Token,
 				TokenType:    model.ACCESS_TOKEN_TYPE,
 				RefreshToken: accessData.RefreshToken,
-				ExpiresIn:    int32(*a.Config().ServiceSettings.SessionLengthSSOInDays * 60 * 60 * 24),
+				ExpiresIn:    a.oauthAccessTokenExpiresIn(oauthApp),
 			}
 		}
 
@@ -243,18 +244,28 @@ func (a *App) GetOAuthAccessToken(clientId, grantType, redirectUri, code, secret
 	} else {
 		// when grantType is refresh_token
 		if result := <-a.Srv.Store.OAuth().GetAccessDataByRefreshToken(refreshToken); result.Err != nil {
+			if !oauthApp.LegacyRefreshTokens {
+				if err := a.revokeGrantForReusedRefreshToken(oauthApp, refreshToken); err != nil {
+					return nil, err
+				}
+			}
+
 			return nil, model.NewAppError("GetOAuthAccessToken", "api.oauth.get_access_token.refresh_token.app_error", nil, "", http.StatusNotFound)
 		} else {
 			accessData = result.Data.(*model.AccessData)
 		}
 
+		if accessData.ClientId != oauthApp.Id {
+			return nil, model.NewAppError("GetOAuthAccessToken", "api.oauth.get_access_token.refresh_token.app_error", nil, "", http.StatusNotFound)
+		}
+
 		if result := <-a.Srv.Store.User().Get(accessData.UserId); result.Err != nil {
 			return nil, model.NewAppError("GetOAuthAccessToken", "api.oauth.get_access_token.internal_user.app_error", nil, "", http.StatusNotFound)
 		} else {
 			user = result.Data.(*model.User)
 		}
 
-		if access, err := a.newSessionUpdateToken(oauthApp.Name, accessData, user); err != nil {
+		if access, err := a.newSessionUpdateToken(oauthApp, accessData, user); err != nil {
 			return nil, err
 		} else {
 			accessRsp = access
@@ -264,11 +275,49 @@ func (a *App) GetOAuthAccessToken(clientId, grantType, redirectUri, code, secret
 	return accessRsp, nil
 }
 
-func (a *App) newSession(appName string, user *model.User) (*model.Session, *model.AppError) {
+// revokeGrantForReusedRefreshToken revokes the access that was granted to the app if the refresh token has
+// already been replaced, since that means that it's been used by more than one client.
+func (a *App) revokeGrantForReusedRefreshToken(oauthApp *model.OAuthApp, refreshToken string) *model.AppError {
+	result := <-a.Srv.Store.OAuth().GetAccessDataByPreviousRefreshToken(refreshToken)
+	if result.Err != nil {
+		if result.Err.StatusCode == http.StatusNotFound {
+			return nil
+		}
+		return result.Err
+	}
+	accessData := result.Data.(*model.AccessData)
+
+	if accessData.ClientId != oauthApp.Id {
+		return nil
+	}
+
+	mlog.Warn("Revoking OAuth access after a refresh token was reused", mlog.String("client_id", oauthApp.Id), mlog.String("user_id", accessData.UserId))
+
+	if err := a.RevokeAccessToken(accessData.Token); err != nil {
+		return err
+	}
+
+	return model.NewAppError("GetOAuthAccessToken", "api.oauth.get_access_token.refresh_token_reused.app_error", nil, "", http.StatusBadRequest)
+}
+
+// oauthAccessTokenExpiresIn returns the number of seconds that the app's access tokens last for.
+func (a *App) oauthAccessTokenExpiresIn(oauthApp *model.OAuthApp) int32 {
+	if oauthApp.AccessTokenExpiresIn > 0 {
+		return int32(oauthApp.AccessTokenExpiresIn)
+	}
+
+	return int32(*a.Config().ServiceSettings.SessionLengthSSOInDays * 60 * 60 * 24)
+}
+
+func (a *App) newSession(oauthApp *model.OAuthApp, user *model.User) (*model.Session, *model.AppError) {
 	// set new token an session
 	session := &model.Session{UserId: user.Id, Roles: user.Roles, IsOAuth: true}
-	session.SetExpireInDays(*a.Config().ServiceSettings.SessionLengthSSOInDays)
-	session.AddProp(model.SESSION_PROP_PLATFORM, appName)
+	if oauthApp.AccessTokenExpiresIn > 0 {
+		session.ExpiresAt = model.GetMillis() + oauthApp.AccessTokenExpiresIn*1000
+	} else {
+		session.SetExpireInDays(*a.Config().ServiceSettings.SessionLengthSSOInDays)
+	}
+	session.AddProp(model.SESSION_PROP_PLATFORM, oauthApp.Name)
 	session.AddProp(model.SESSION_PROP_OS, "OAuth2")
 	session.AddProp(model.SESSION_PROP_BROWSER, "OAuth2")
 
@@ -282,17 +331,21 @@ func (a *App) newSession(appName string, user *model.User) (*model.Session, *mod
 	return session, nil
 }
 
-func (a *App) newSessionUpdateToken(appName string, accessData *model.AccessData, user *model.User) (*model.AccessResponse, *model.AppError) {
+func (a *App) newSessionUpdateToken(oauthApp *model.OAuthApp, accessData *model.AccessData, user *model.User) (*model.AccessResponse, *model.AppError) {
 	var session *model.Session
 	<-a.Srv.Store.Session().Remove(accessData.Token) //remove the previous session
 
-	if result, err := a.newSession(appName, user); err != nil {
+	if result, err := a.newSession(oauthApp, user); err != nil {
 		return nil, err
 	} else {
 		session = result
 	}
 
 	accessData.Token = session.Token
+	if !oauthApp.LegacyRefreshTokens {
+		// remembered so that anyone using it again can be detected
+		accessData.PreviousRefreshToken = accessData.RefreshToken
+	}
 	accessData.RefreshToken = model.NewId()
 	accessData.ExpiresAt = session.ExpiresAt
 	if result := <-a.Srv.Store.OAuth().UpdateAccessData(accessData); result.Err != nil {
@@ -303,7 +356,7 @@ func (a *App) newSessionUpdateToken(appName string, accessData *model.AccessData
 		AccessToken:  session.Token,
 		RefreshToken: accessData.RefreshToken,
 		TokenType:    model.ACCESS_TOKEN_TYPE,
-		ExpiresIn:    int32(*a.Config().ServiceSettings.SessionLengthSSOInDays * 60 * 60 * 24),
+		ExpiresIn:    a.oauthAccessTokenExpiresIn(oauthApp),
 	}
 
 	return accessRsp, nil
@@ -427,6 +480,97 @@ func (a *App) RevokeAccessToken(token string) *model.AppError {
 	return nil
 }
 
+// IntrospectOAuthToken describes an access or refresh token to the app whose credentials are given so that the
+// app can check whether it's still active. Apps can only introspect tokens issued to them unless they're trusted.
+func (a *App) IntrospectOAuthToken(clientId, secret, token, tokenTypeHint string) (*model.IntrospectionResponse, *model.AppError) {
+	if !a.Config().ServiceSettings.EnableOAuthServiceProvider {
+		return nil, model.NewAppError("IntrospectOAuthToken", "api.oauth.introspect.disabled.app_error", nil, "", http.StatusNotImplemented)
+	}
+
+	var oauthApp *model.OAuthApp
+	if result := <-a.Srv.Store.OAuth().GetApp(clientId); result.Err != nil {
+		return nil, model.NewAppError("IntrospectOAuthToken", "api.oauth.introspect.credentials.app_error", nil, "", http.StatusUnauthorized)
+	} else {
+		oauthApp = result.Data.(*model.OAuthApp)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(oauthApp.ClientSecret), []byte(secret)) != 1 {
+		return nil, model.NewAppError("IntrospectOAuthToken", "api.oauth.introspect.credentials.app_error", nil, "", http.StatusUnauthorized)
+	}
+
+	var introspection *model.IntrospectionResponse
+	if tokenTypeHint == model.REFRESH_TOKEN_GRANT_TYPE {
+		if introspection = a.introspectOAuthRefreshToken(token); introspection == nil {
+			introspection = a.introspectOAuthAccessToken(token)
+		}
+	} else {
+		if introspection = a.introspectOAuthAccessToken(token); introspection == nil {
+			introspection = a.introspectOAuthRefreshToken(token)
+		}
+	}
+
+	if introspection == nil || (introspection.ClientId != oauthApp.Id && !oauthApp.IsTrusted) {
+		return &model.IntrospectionResponse{Active: false}, nil
+	}
+
+	return introspection, nil
+}
+
+func (a *App) introspectOAuthAccessToken(token string) *model.IntrospectionResponse {
+	result := <-a.Srv.Store.OAuth().GetAccessData(token)
+	if result.Err != nil {
+		return nil
+	}
+	accessData := result.Data.(*model.AccessData)
+
+	sresult := <-a.Srv.Store.Session().Get(token)
+	if sresult.Err != nil {
+		return nil
+	}
+	session := sresult.Data.(*model.Session)
+
+	if session.IsExpired() || !session.IsOAuth {
+		return nil
+	}
+
+	user, err := a.GetUser(accessData.UserId)
+	if err != nil || user.DeleteAt != 0 {
+		return nil
+	}
+
+	return &model.IntrospectionResponse{
+		Active:    true,
+		Scope:     accessData.Scope,
+		ClientId:  accessData.ClientId,
+		Username:  user.Username,
+		TokenType: model.ACCESS_TOKEN_TYPE,
+		ExpiresAt: session.ExpiresAt / 1000,
+		IssuedAt:  session.CreateAt / 1000,
+		Subject:   user.Id,
+	}
+}
+
+func (a *App) introspectOAuthRefreshToken(token string) *model.IntrospectionResponse {
+	result := <-a.Srv.Store.OAuth().GetAccessDataByRefreshToken(token)
+	if result.Err != nil {
+		return nil
+	}
+	accessData := result.Data.(*model.AccessData)
+
+	user, err := a.GetUser(accessData.UserId)
+	if err != nil || user.DeleteAt != 0 {
+		return nil
+	}
+
+	return &model.IntrospectionResponse{
+		Active:   true,
+		Scope:    accessData.Scope,
+		ClientId: accessData.ClientId,
+		Username: user.Username,
+		Subject:  user.Id,
+	}
+}
+
 func (a *App) CompleteOAuth(service string, body io.ReadCloser, teamId string, props map[string]string) (*model.User, *model.AppError) {
 	defer body.Close()
 
@@ -0,0 +1,146 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dyatlov/go-opengraph/opengraph"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+const (
+	OPEN_GRAPH_METADATA_CACHE_SIZE       = 10000
+	OPEN_GRAPH_METADATA_CACHE_EXPIRY_SEC = 3600
+	OPEN_GRAPH_FETCH_TIMEOUT             = 10 * time.Second
+	OPEN_GRAPH_FETCH_MAX_BYTES           = 1024 * 1024
+
+	UNTRUSTED_FETCH_MAX_REDIRECTS = 5
+)
+
+var openGraphDataCache = utils.NewLru(OPEN_GRAPH_METADATA_CACHE_SIZE)
+
+var openGraphURLRegex = regexp.MustCompile(`https?://[^\s<>()\[\]"'` + "`" + `]+`)
+
+// GetOpenGraphMetadata returns the OpenGraph metadata for the given URL, fetching it if it isn't
+// already cached. Any image URLs are rewritten to go through the image proxy if one is configured.
+func (a *App) GetOpenGraphMetadata(requestURL string) *opengraph.OpenGraph {
+	var og *opengraph.OpenGraph
+	if cached, ok := openGraphDataCache.Get(requestURL); ok {
+		og = cached.(*opengraph.OpenGraph)
+	} else {
+		og = a.fetchOpenGraphMetadata(requestURL)
+		openGraphDataCache.AddWithExpiresInSecs(requestURL, og, OPEN_GRAPH_METADATA_CACHE_EXPIRY_SEC)
+	}
+
+	if toProxyURL := a.ImageProxyAdder(); toProxyURL != nil {
+		return openGraphDataWithProxyAddedToImageURLs(og, toProxyURL)
+	}
+
+	return og
+}
+
+func (a *App) fetchOpenGraphMetadata(requestURL string) *opengraph.OpenGraph {
+	og := opengraph.NewOpenGraph()
+
+	client := a.HTTPClient(false)
+	client.Timeout = OPEN_GRAPH_FETCH_TIMEOUT
+	client.CheckRedirect = checkUntrustedRedirect
+
+	res, err := client.Get(requestURL)
+	if err != nil {
+		mlog.Error(fmt.Sprintf("GetOpenGraphMetadata request failed for url=%v with err=%v", requestURL, err.Error()))
+		return og
+	}
+	// The body is closed without reading the rest of it so that a large response isn't downloaded past the limit
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		mlog.Warn(fmt.Sprintf("GetOpenGraphMetadata request failed for url=%v with status=%v", requestURL, res.StatusCode))
+		return og
+	}
+
+	contentType := res.Header.Get("Content-Type")
+	body := forceHTMLEncodingToUTF8(io.LimitReader(res.Body, OPEN_GRAPH_FETCH_MAX_BYTES), contentType)
+
+	if err := og.ProcessHTML(body); err != nil {
+		mlog.Error(fmt.Sprintf("GetOpenGraphMetadata processing failed for url=%v with err=%v", requestURL, err.Error()))
+	}
+
+	makeOpenGraphURLsAbsolute(og, requestURL)
+
+	return og
+}
+
+// checkUntrustedRedirect limits the redirects followed while fetching content from untrusted URLs.
+// The destination of each redirect is still subject to the untrusted connection filtering done when
+// dialing, so this only needs to reject unexpected schemes and redirect loops.
+func checkUntrustedRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= UNTRUSTED_FETCH_MAX_REDIRECTS {
+		return errors.New("stopped after too many redirects")
+	}
+
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return errors.Errorf("redirect to unsupported scheme %v", req.URL.Scheme)
+	}
+
+	return nil
+}
+
+// openGraphDataWithProxyAddedToImageURLs returns a copy of ogdata with the image URLs replaced so
+// that they're fetched through the image proxy. The original is left untouched since it may be
+// shared through the cache.
+func openGraphDataWithProxyAddedToImageURLs(ogdata *opengraph.OpenGraph, toProxyURL func(string) string) *opengraph.OpenGraph {
+	copy := *ogdata
+	copy.Images = make([]*opengraph.Image, len(ogdata.Images))
+
+	for i, image := range ogdata.Images {
+		proxied := *image
+
+		var url string
+		if image.SecureURL != "" {
+			url = image.SecureURL
+		} else {
+			url = image.URL
+		}
+
+		proxied.URL = ""
+		proxied.SecureURL = toProxyURL(url)
+
+		copy.Images[i] = &proxied
+	}
+
+	return &copy
+}
+
+// InvalidateOpenGraphCacheForMessage removes the cached OpenGraph metadata for every link found in
+// the given message on every server in the cluster so that it's fetched again the next time it's requested.
+func (a *App) InvalidateOpenGraphCacheForMessage(message string) {
+	a.InvalidateOpenGraphCacheForMessageSkipClusterSend(message)
+
+	if a.Cluster != nil {
+		msg := &model.ClusterMessage{
+			Event:    model.CLUSTER_EVENT_INVALIDATE_CACHE_FOR_OPEN_GRAPH,
+			SendType: model.CLUSTER_SEND_BEST_EFFORT,
+			Data:     message,
+		}
+		a.Cluster.SendClusterMessage(msg)
+	}
+}
+
+func (a *App) InvalidateOpenGraphCacheForMessageSkipClusterSend(message string) {
+	for _, link := range openGraphURLRegex.FindAllString(message, -1) {
+		openGraphDataCache.Remove(link)
+		openGraphDataCache.Remove(strings.TrimRight(link, ".,;:!?"))
+	}
+}
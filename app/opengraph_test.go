@@ -0,0 +1,112 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/dyatlov/go-opengraph/opengraph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const testOpenGraphHTML = `
+<html><head>
+  <meta property="og:type" content="article" />
+  <meta property="og:title" content="Test Title" />
+  <meta property="og:image" content="http://example.com/image.png" />
+</head><body></body></html>`
+
+func TestGetOpenGraphMetadataCache(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.ServiceSettings.AllowedUntrustedInternalConnections = "localhost 127.0.0.1"
+	})
+
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, testOpenGraphHTML)
+	}))
+	defer ts.Close()
+
+	link := ts.URL + "/cached/"
+
+	og := th.App.GetOpenGraphMetadata(link)
+	assert.Equal(t, "Test Title", og.Title)
+	assert.Equal(t, 1, requests)
+
+	og = th.App.GetOpenGraphMetadata(link)
+	assert.Equal(t, "Test Title", og.Title)
+	assert.Equal(t, 1, requests, "second request should have been served from the cache")
+
+	post, err := th.App.CreatePostAsUser(&model.Post{
+		UserId:    th.BasicUser.Id,
+		ChannelId: th.BasicChannel.Id,
+		Message:   "see " + link + ".",
+	})
+	require.Nil(t, err)
+
+	post.Message = "never mind"
+	_, err = th.App.UpdatePost(post, true)
+	require.Nil(t, err)
+
+	th.App.GetOpenGraphMetadata(link)
+	assert.Equal(t, 2, requests, "editing the post should have invalidated the cached link")
+}
+
+func TestGetOpenGraphMetadataRedirectToInternalIP(t *testing.T) {
+	th := Setup()
+	defer th.TearDown()
+
+	target := 0
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target++
+		fmt.Fprint(w, testOpenGraphHTML)
+	}))
+	defer internal.Close()
+
+	redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL+"/internal/", http.StatusFound)
+	}))
+	defer redirector.Close()
+
+	redirectorURL, _ := url.Parse(redirector.URL)
+
+	// Only the redirecting host is trusted, so following the redirect to 127.0.0.1 must fail.
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.ServiceSettings.AllowedUntrustedInternalConnections = "localhost"
+	})
+
+	og := th.App.GetOpenGraphMetadata("http://localhost:" + redirectorURL.Port() + "/redirect/")
+	assert.Equal(t, "", og.Title)
+	assert.Equal(t, 0, target)
+}
+
+func TestOpenGraphDataWithProxyAddedToImageURLs(t *testing.T) {
+	og := opengraph.NewOpenGraph()
+	og.Images = []*opengraph.Image{
+		{URL: "http://example.com/image.png"},
+		{URL: "http://example.com/other.png", SecureURL: "https://example.com/other.png"},
+	}
+
+	proxied := openGraphDataWithProxyAddedToImageURLs(og, func(url string) string {
+		return "https://proxy.example.com/" + url
+	})
+
+	assert.Equal(t, "", proxied.Images[0].URL)
+	assert.Equal(t, "https://proxy.example.com/http://example.com/image.png", proxied.Images[0].SecureURL)
+	assert.Equal(t, "https://proxy.example.com/https://example.com/other.png", proxied.Images[1].SecureURL)
+
+	assert.Equal(t, "http://example.com/image.png", og.Images[0].URL, "original should not be modified")
+	assert.Equal(t, "", og.Images[0].SecureURL, "original should not be modified")
+}
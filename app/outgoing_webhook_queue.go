@@ -0,0 +1,56 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"sync"
+)
+
+// outgoingWebhookQueues sends the requests for each outgoing webhook callback URL one at a time, in the order that
+// they were queued, so that a hook's events are received in the order that the posts were made. Requests for
+// different URLs are still sent concurrently. Its zero value is ready to use.
+type outgoingWebhookQueues struct {
+	lock sync.Mutex
+
+	// pending holds the requests waiting to be sent for each URL that has a worker running
+	pending map[string][]func()
+}
+
+// queue runs send after the requests already queued with the same key, starting a worker with start if there isn't
+// one running for that key.
+func (q *outgoingWebhookQueues) queue(key string, send func(), start func(func())) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.pending == nil {
+		q.pending = make(map[string][]func())
+	}
+
+	pending, running := q.pending[key]
+	q.pending[key] = append(pending, send)
+
+	if !running {
+		start(func() {
+			q.work(key)
+		})
+	}
+}
+
+func (q *outgoingWebhookQueues) work(key string) {
+	for {
+		q.lock.Lock()
+		pending := q.pending[key]
+		if len(pending) == 0 {
+			delete(q.pending, key)
+			q.lock.Unlock()
+			return
+		}
+
+		send := pending[0]
+		q.pending[key] = pending[1:]
+		q.lock.Unlock()
+
+		send()
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutgoingWebhookQueues(t *testing.T) {
+	t.Run("requests for a URL are sent in order", func(t *testing.T) {
+		var q outgoingWebhookQueues
+		var wg sync.WaitGroup
+
+		var lock sync.Mutex
+		var sent []int
+
+		workers := 0
+		start := func(f func()) {
+			workers++
+			go f()
+		}
+
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			q.queue("hook http://example.com", func(i int) func() {
+				return func() {
+					defer wg.Done()
+
+					// later requests would overtake the slow ones if they were sent concurrently
+					if i%5 == 0 {
+						time.Sleep(time.Millisecond)
+					}
+
+					lock.Lock()
+					sent = append(sent, i)
+					lock.Unlock()
+				}
+			}(i), start)
+		}
+
+		wg.Wait()
+
+		require.Len(t, sent, 50)
+		for i, value := range sent {
+			assert.Equal(t, i, value)
+		}
+		assert.True(t, workers < 50, "a worker should send every request queued while it's running")
+	})
+
+	t.Run("requests for different URLs are sent concurrently", func(t *testing.T) {
+		var q outgoingWebhookQueues
+
+		blocked := make(chan struct{})
+		sent := make(chan struct{})
+
+		q.queue("hook http://example.com/slow", func() {
+			<-blocked
+		}, func(f func()) { go f() })
+		q.queue("hook http://example.com/fast", func() {
+			close(sent)
+		}, func(f func()) { go f() })
+
+		select {
+		case <-sent:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the request was held up by another URL's request")
+		}
+
+		close(blocked)
+	})
+
+	t.Run("the worker stops once its queue is empty", func(t *testing.T) {
+		var q outgoingWebhookQueues
+
+		done := make(chan struct{})
+		q.queue("hook http://example.com", func() {}, func(f func()) {
+			go func() {
+				f()
+				close(done)
+			}()
+		})
+		<-done
+
+		q.lock.Lock()
+		defer q.lock.Unlock()
+		assert.Empty(t, q.pending)
+	})
+}
@@ -92,6 +92,10 @@ func (api *PluginAPI) UpdateUser(user *model.User) (*model.User, *model.AppError
 	return api.app.UpdateUser(user, true)
 }
 
+func (api *PluginAPI) EnsureBotUser() (*model.User, *model.AppError) {
+	return api.app.EnsureSystemBot()
+}
+
 func (api *PluginAPI) CreateChannel(channel *model.Channel) (*model.Channel, *model.AppError) {
 	return api.app.CreateChannel(channel, false)
 }
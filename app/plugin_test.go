@@ -52,8 +52,14 @@ func TestServePluginRequest(t *testing.T) {
 
 	w := httptest.NewRecorder()
 	r := httptest.NewRequest("GET", "/plugins/foo/bar", nil)
+	r.Header.Set(model.HEADER_REQUEST_ID, "plugin-request")
 	th.App.ServePluginRequest(w, r)
 	assert.Equal(t, http.StatusNotImplemented, w.Result().StatusCode)
+	assert.Equal(t, "plugin-request", w.Result().Header.Get(model.HEADER_REQUEST_ID))
+
+	err := model.AppErrorFromJson(w.Result().Body)
+	require.NotNil(t, err)
+	assert.Equal(t, "plugin-request", err.RequestId)
 }
 
 func TestHandlePluginRequest(t *testing.T) {
@@ -4,6 +4,7 @@
 package app
 
 import (
+	"bytes"
 	"crypto/hmac"
 	"crypto/sha1"
 	"encoding/hex"
@@ -26,6 +27,12 @@ import (
 var linkWithTextRegex = regexp.MustCompile(`<([^<\|]+)\|([^>]+)>`)
 
 func (a *App) CreatePostAsUser(post *model.Post) (*model.Post, *model.AppError) {
+	return a.createPostAsUser(post, nil)
+}
+
+// createPostAsUser is like CreatePostAsUser except that serverProps are added to the post once the props that it
+// came with have been sanitized, so that it can have props that users aren't allowed to set themselves.
+func (a *App) createPostAsUser(post *model.Post, serverProps model.StringInterface) (*model.Post, *model.AppError) {
 	// Check that channel has not been deleted
 	var channel *model.Channel
 	if result := <-a.Srv.Store.Channel().Get(post.ChannelId, true); result.Err != nil {
@@ -45,7 +52,7 @@ func (a *App) CreatePostAsUser(post *model.Post) (*model.Post, *model.AppError)
 		return nil, err
 	}
 
-	if rp, err := a.CreatePost(post, channel, true); err != nil {
+	if rp, err := a.createPost(post, channel, true, serverProps); err != nil {
 		if err.Id == "api.post.create_post.root_id.app_error" ||
 			err.Id == "api.post.create_post.channel_root_id.app_error" ||
 			err.Id == "api.post.create_post.parent_id.app_error" {
@@ -79,14 +86,12 @@ func (a *App) CreatePostAsUser(post *model.Post) (*model.Post, *model.AppError)
 	} else {
 		// Update the LastViewAt only if the post does not have from_webhook prop set (eg. Zapier app)
 		if _, ok := post.Props["from_webhook"]; !ok {
-			if result := <-a.Srv.Store.Channel().UpdateLastViewedAt([]string{post.ChannelId}, post.UserId); result.Err != nil {
-				mlog.Error(fmt.Sprintf("Encountered error updating last viewed, channel_id=%s, user_id=%s, err=%v", post.ChannelId, post.UserId, result.Err))
+			if _, err := a.updateLastViewedAt([]string{post.ChannelId}, post.UserId); err != nil {
+				mlog.Error(fmt.Sprintf("Encountered error updating last viewed, channel_id=%s, user_id=%s, err=%v", post.ChannelId, post.UserId, err))
 			}
 
 			if *a.Config().ServiceSettings.EnableChannelViewedMessages {
-				message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_CHANNEL_VIEWED, "", "", post.UserId, nil)
-				message.Add("channel_id", post.ChannelId)
-				a.Publish(message)
+				a.publishChannelViewed(post.ChannelId, post.UserId)
 			}
 		}
 
@@ -95,6 +100,98 @@ func (a *App) CreatePostAsUser(post *model.Post) (*model.Post, *model.AppError)
 
 }
 
+// checkPostFileIds returns an error if the post has more files than FileSettings.MaxFileAttachments, or if any of
+// them can't be attached to it because it doesn't exist, was uploaded by someone else or to another channel, or is
+// already attached to another post.
+func (a *App) checkPostFileIds(post *model.Post) *model.AppError {
+	if len(post.FileIds) == 0 {
+		return nil
+	}
+
+	// There's a rare bug where the client sends up duplicate FileIds so protect against that
+	post.FileIds = utils.RemoveDuplicatesFromStringArray(post.FileIds)
+
+	if max := *a.Config().FileSettings.MaxFileAttachments; len(post.FileIds) > max {
+		return model.NewAppError("checkPostFileIds", "app.post.check_file_ids.too_many.app_error", map[string]interface{}{"Count": len(post.FileIds), "Max": max}, "user_id="+post.UserId, http.StatusBadRequest)
+	}
+
+	for _, fileId := range post.FileIds {
+		result := <-a.Srv.Store.FileInfo().Get(fileId)
+		if result.Err != nil && result.Err.StatusCode != http.StatusNotFound {
+			return result.Err
+		}
+
+		if result.Err == nil {
+			info := result.Data.(*model.FileInfo)
+
+			// Files uploaded before they were saved with a channel can be attached in any channel
+			if info.CreatorId == post.UserId && (info.ChannelId == "" || info.ChannelId == post.ChannelId) && (info.PostId == "" || info.PostId == post.Id) {
+				continue
+			}
+		}
+
+		return model.NewAppError("checkPostFileIds", "app.post.check_file_ids.invalid.app_error", map[string]interface{}{"FileId": fileId}, "user_id="+post.UserId, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+// CheckChannelMentionsLimit returns an error if the post uses @here, @channel or @all in a channel with more members
+// than TeamSettings.MaxNotificationsPerChannel, where they don't notify anyone, so that the author can confirm that
+// they want to send it anyway. The author is also warned with an ephemeral post.
+func (a *App) CheckChannelMentionsLimit(post *model.Post) *model.AppError {
+	var channel *model.Channel
+	if result := <-a.Srv.Store.Channel().Get(post.ChannelId, true); result.Err != nil {
+		return model.NewAppError("CheckChannelMentionsLimit", "api.context.invalid_param.app_error", map[string]interface{}{"Name": "post.channel_id"}, result.Err.Error(), http.StatusBadRequest)
+	} else {
+		channel = result.Data.(*model.Channel)
+	}
+
+	// the mentions are removed with a warning of their own when the channel doesn't allow them
+	if channel.IsGroupOrDirect() || channel.DisableChannelMentions {
+		return nil
+	}
+
+	var mention string
+	if m := GetExplicitMentions(post.Message, map[string][]string{}); m.ChannelMentioned {
+		mention = "@channel"
+	} else if m.AllMentioned {
+		mention = "@all"
+	} else if m.HereMentioned {
+		mention = "@here"
+	} else {
+		return nil
+	}
+
+	maxNotifications := *a.Config().TeamSettings.MaxNotificationsPerChannel
+	if a.Srv.Store.Channel().GetMemberCountFromCache(channel.Id) <= maxNotifications {
+		return nil
+	}
+
+	if a.userIsModeratedInChannel(post.UserId, channel.Id, model.CHANNEL_MODERATED_PERMISSION_USE_CHANNEL_MENTIONS) {
+		return nil
+	}
+
+	T := utils.T
+	if result := <-a.Srv.Store.User().Get(post.UserId); result.Err == nil {
+		T = utils.GetUserTranslations(result.Data.(*model.User).Locale)
+	}
+
+	a.SendEphemeralPost(
+		post.UserId,
+		&model.Post{
+			ChannelId: channel.Id,
+			ParentId:  post.ParentId,
+			RootId:    post.RootId,
+			UserId:    post.UserId,
+			Message:   T("api.post.check_channel_mentions_limit.warning", map[string]interface{}{"Mention": mention, "Users": maxNotifications}),
+			CreateAt:  model.GetMillis(),
+		},
+	)
+
+	return model.NewAppError("CheckChannelMentionsLimit", "api.post.check_channel_mentions_limit.app_error", map[string]interface{}{"Mention": mention, "Users": maxNotifications}, "channel_id="+channel.Id, http.StatusPreconditionRequired)
+}
+
 func (a *App) CreatePostMissingChannel(post *model.Post, triggerWebhooks bool) (*model.Post, *model.AppError) {
 	var channel *model.Channel
 	cchan := a.Srv.Store.Channel().Get(post.ChannelId, true)
@@ -108,7 +205,19 @@ func (a *App) CreatePostMissingChannel(post *model.Post, triggerWebhooks bool) (
 }
 
 func (a *App) CreatePost(post *model.Post, channel *model.Channel, triggerWebhooks bool) (*model.Post, *model.AppError) {
+	return a.createPost(post, channel, triggerWebhooks, nil)
+}
+
+func (a *App) createPost(post *model.Post, channel *model.Channel, triggerWebhooks bool, serverProps model.StringInterface) (*model.Post, *model.AppError) {
+	// Fail before doing anything else so that clients can queue the post to try again once the database is writable
+	if err := a.CheckReadOnly(); err != nil {
+		return nil, err
+	}
+
 	post.SanitizeProps()
+	for key, value := range serverProps {
+		post.AddProp(key, value)
+	}
 
 	var pchan store.StoreChannel
 	if len(post.RootId) > 0 {
@@ -160,6 +269,36 @@ func (a *App) CreatePost(post *model.Post, channel *model.Channel, triggerWebhoo
 		return nil, err
 	}
 
+	if err := a.checkPostProps(post); err != nil {
+		return nil, err
+	}
+
+	// System messages and custom post types may be shown by their type alone
+	if (post.Type == model.POST_DEFAULT || post.Type == model.POST_SLACK_ATTACHMENT) && post.IsEmpty() {
+		return nil, model.NewAppError("createPost", "api.post.create_post.empty.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if err := a.checkPostFileIds(post); err != nil {
+		return nil, err
+	}
+
+	filteredWords, err := a.filterPostWords(post, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	blockedDomains, err := a.checkPostLinks(post)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets, err := a.checkPostForSecrets(post)
+	if err != nil {
+		return nil, err
+	}
+
+	a.warnAboutFourByteCharacters(post)
+
 	if a.PluginsReady() {
 		if newPost, rejectionReason := a.PluginEnv.Hooks().MessageWillBePosted(post); newPost == nil {
 			return nil, model.NewAppError("createPost", "Post rejected by plugin. "+rejectionReason, nil, "", http.StatusBadRequest)
@@ -168,8 +307,14 @@ func (a *App) CreatePost(post *model.Post, channel *model.Channel, triggerWebhoo
 		}
 	}
 
+	save := a.Srv.Store.Post().Save
+	if *a.Config().ServiceSettings.ReliableWebsocketEvents {
+		// The posted event is saved with the post so that it's sent even if the server stops before sending it
+		save = a.Srv.Store.Post().SaveWithWebSocketEvent
+	}
+
 	var rpost *model.Post
-	if result := <-a.Srv.Store.Post().Save(post); result.Err != nil {
+	if result := <-save(post); result.Err != nil {
 		return nil, result.Err
 	} else {
 		rpost = result.Data.(*model.Post)
@@ -207,13 +352,58 @@ func (a *App) CreatePost(post *model.Post, channel *model.Channel, triggerWebhoo
 		}
 	}
 
+	if len(rpost.RootId) > 0 {
+		if err := a.updateThreadForReply(rpost, channel, parentPostList); err != nil {
+			mlog.Error(fmt.Sprintf("Encountered error updating thread, post_id=%s, root_id=%s, err=%v", rpost.Id, rpost.RootId, err), mlog.String("post_id", rpost.Id))
+		}
+	}
+
 	if err := a.handlePostEvents(rpost, user, channel, triggerWebhooks, parentPostList); err != nil {
 		return nil, err
 	}
 
+	a.flagFilteredWords(rpost, filteredWords)
+	a.warnAboutBlockedLinks(rpost, blockedDomains)
+	a.handleSecretsInPost(rpost, secrets)
+
 	return rpost, nil
 }
 
+// checkPostProps removes the username and icon overrides from the post's props unless they're allowed, and
+// returns an error if the props the server knows about are invalid or if they're too large once serialized.
+func (a *App) checkPostProps(post *model.Post) *model.AppError {
+	cfg := a.Config()
+
+	overrides := map[string]bool{
+		"override_username": cfg.ServiceSettings.EnablePostUsernameOverride,
+		"override_icon_url": cfg.ServiceSettings.EnablePostIconOverride,
+	}
+	for prop, allowed := range overrides {
+		value, ok := post.Props[prop]
+		if !ok {
+			continue
+		}
+
+		if !allowed {
+			delete(post.Props, prop)
+		} else if _, ok := value.(string); !ok {
+			return model.NewAppError("checkPostProps", "app.post.check_props.override.app_error", map[string]interface{}{"Prop": prop}, "", http.StatusBadRequest)
+		}
+	}
+
+	if attachments, ok := post.Props["attachments"]; ok {
+		if err := model.ValidateSlackAttachments(attachments); err != nil {
+			return err
+		}
+	}
+
+	if size := post.PropsSize(); size > *cfg.ServiceSettings.MaxPostPropsSize {
+		return model.NewAppError("checkPostProps", "app.post.check_props.too_large.app_error", map[string]interface{}{"Size": size, "Max": *cfg.ServiceSettings.MaxPostPropsSize}, "id="+post.Id, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
 // FillInPostProps should be invoked before saving posts to fill in properties such as
 // channel_mentions.
 //
@@ -280,10 +470,8 @@ func (a *App) handlePostEvents(post *model.Post, user *model.User, channel *mode
 	}
 
 	if triggerWebhooks {
-		a.Go(func() {
-			if err := a.handleWebhookEvents(post, team, channel, user); err != nil {
-				mlog.Error(err.Error())
-			}
+		a.queuePostJob(post, func(overloaded bool) *model.AppError {
+			return a.handleWebhookEvents(post, team, channel, user)
 		})
 	}
 
@@ -296,12 +484,15 @@ func parseSlackAttachment(post *model.Post, attachments []*model.SlackAttachment
 	post.Type = model.POST_SLACK_ATTACHMENT
 
 	for _, attachment := range attachments {
-		attachment.Text = parseSlackLinksToMarkdown(attachment.Text)
-		attachment.Pretext = parseSlackLinksToMarkdown(attachment.Pretext)
+		attachment.Text = parseSlackAttachmentText(attachment, model.SLACK_ATTACHMENT_MARKDOWN_TEXT, attachment.Text)
+		attachment.Pretext = parseSlackAttachmentText(attachment, model.SLACK_ATTACHMENT_MARKDOWN_PRETEXT, attachment.Pretext)
+		attachment.Footer = parseSlackLinksToMarkdown(attachment.Footer)
+		attachment.Color = parseSlackAttachmentColor(attachment.Color)
+		attachment.Timestamp = parseSlackTimestamp(attachment.Timestamp)
 
 		for _, field := range attachment.Fields {
 			if value, ok := field.Value.(string); ok {
-				field.Value = parseSlackLinksToMarkdown(value)
+				field.Value = parseSlackAttachmentText(attachment, model.SLACK_ATTACHMENT_MARKDOWN_FIELDS, value)
 			}
 		}
 	}
@@ -334,6 +525,13 @@ func (a *App) SendEphemeralPost(userId string, post *model.Post) *model.Post {
 }
 
 func (a *App) UpdatePost(post *model.Post, safeUpdate bool) (*model.Post, *model.AppError) {
+	return a.UpdatePostByEditor(post, safeUpdate, "")
+}
+
+// UpdatePostByEditor updates a post, recording the post's previous message and props as an earlier version
+// of it made by editorId. Edits that aren't made by a user, such as by plugins, are recorded as being
+// made by the post's author.
+func (a *App) UpdatePostByEditor(post *model.Post, safeUpdate bool, editorId string) (*model.Post, *model.AppError) {
 	post.SanitizeProps()
 
 	var oldPost *model.Post
@@ -357,9 +555,13 @@ func (a *App) UpdatePost(post *model.Post, safeUpdate bool) (*model.Post, *model
 			return nil, err
 		}
 
-		if a.License() != nil {
-			if *a.Config().ServiceSettings.PostEditTimeLimit != -1 && model.GetMillis() > oldPost.CreateAt+int64(*a.Config().ServiceSettings.PostEditTimeLimit*1000) && post.Message != oldPost.Message {
-				err := model.NewAppError("UpdatePost", "api.post.update_post.permissions_time_limit.app_error", map[string]interface{}{"timeLimit": *a.Config().ServiceSettings.PostEditTimeLimit}, "", http.StatusBadRequest)
+		if post.Message != oldPost.Message {
+			userId := editorId
+			if userId == "" {
+				userId = oldPost.UserId
+			}
+
+			if err := a.CheckPostEditTimeLimit(userId, oldPost); err != nil {
 				return nil, err
 			}
 		}
@@ -379,12 +581,45 @@ func (a *App) UpdatePost(post *model.Post, safeUpdate bool) (*model.Post, *model
 		newPost.HasReactions = post.HasReactions
 		newPost.FileIds = post.FileIds
 		newPost.Props = post.Props
+
+		// The preview of a forwarded post can only be set when it's forwarded, so it's kept when the props are replaced
+		if preview, ok := oldPost.Props[model.POST_PROPS_FORWARDED_POST]; ok {
+			newPost.AddProp(model.POST_PROPS_FORWARDED_POST, preview)
+		}
+
+		if err := a.checkPostProps(newPost); err != nil {
+			return nil, err
+		}
+
+		// Posts that had more files before the limit was lowered can still be edited
+		if max := *a.Config().FileSettings.MaxFileAttachments; len(newPost.FileIds) > max && len(newPost.FileIds) > len(oldPost.FileIds) {
+			return nil, model.NewAppError("UpdatePost", "app.post.check_file_ids.too_many.app_error", map[string]interface{}{"Count": len(newPost.FileIds), "Max": max}, "id="+post.Id, http.StatusBadRequest)
+		}
 	}
 
 	if err := a.FillInPostProps(post, nil); err != nil {
 		return nil, err
 	}
 
+	// Only edits to the message are checked so that pinning or reacting to an old post doesn't flag it again
+	var filteredWords, blockedDomains, secrets []string
+	if newPost.Message != oldPost.Message {
+		var err *model.AppError
+		if filteredWords, err = a.filterPostWords(newPost, nil); err != nil {
+			return nil, err
+		}
+
+		if blockedDomains, err = a.checkPostLinks(newPost); err != nil {
+			return nil, err
+		}
+
+		if secrets, err = a.checkPostForSecrets(newPost); err != nil {
+			return nil, err
+		}
+
+		a.warnAboutFourByteCharacters(newPost)
+	}
+
 	if a.PluginsReady() {
 		if pluginModifiedPost, rejectionReason := a.PluginEnv.Hooks().MessageWillBeUpdated(newPost, oldPost); pluginModifiedPost == nil {
 			return nil, model.NewAppError("createPost", "Post rejected by plugin. "+rejectionReason, nil, "", http.StatusBadRequest)
@@ -393,11 +628,26 @@ func (a *App) UpdatePost(post *model.Post, safeUpdate bool) (*model.Post, *model
 		}
 	}
 
+	// The store reuses the old post to keep a deleted copy of it, so the history has to be taken first
+	var history *model.PostHistory
+	if newPost.Message != oldPost.Message || model.StringInterfaceToJson(newPost.Props) != model.StringInterfaceToJson(oldPost.Props) {
+		if editorId == "" {
+			editorId = oldPost.UserId
+		}
+		history = model.NewPostHistory(oldPost, editorId, model.GetMillis())
+	}
+
 	if result := <-a.Srv.Store.Post().Update(newPost, oldPost); result.Err != nil {
 		return nil, result.Err
 	} else {
 		rpost := result.Data.(*model.Post)
 
+		if history != nil {
+			if result := <-a.Srv.Store.Post().SaveHistory(history); result.Err != nil {
+				mlog.Error(fmt.Sprintf("Failed to save the edit history of post_id=%v, err=%v", rpost.Id, result.Err))
+			}
+		}
+
 		if a.PluginsReady() {
 			a.Go(func() {
 				a.PluginEnv.Hooks().MessageHasBeenUpdated(newPost, oldPost)
@@ -415,15 +665,27 @@ func (a *App) UpdatePost(post *model.Post, safeUpdate bool) (*model.Post, *model
 			})
 		}
 
+		if oldPost.Message != rpost.Message {
+			a.InvalidateOpenGraphCacheForMessage(oldPost.Message)
+		}
+
 		a.sendUpdatedPostEvent(rpost)
 
 		a.InvalidateCacheForChannelPosts(rpost.ChannelId)
 
+		a.flagFilteredWords(rpost, filteredWords)
+		a.warnAboutBlockedLinks(rpost, blockedDomains)
+		a.handleSecretsInPost(rpost, secrets)
+
 		return rpost, nil
 	}
 }
 
 func (a *App) PatchPost(postId string, patch *model.PostPatch) (*model.Post, *model.AppError) {
+	return a.PatchPostByEditor(postId, patch, "")
+}
+
+func (a *App) PatchPostByEditor(postId string, patch *model.PostPatch, editorId string) (*model.Post, *model.AppError) {
 	post, err := a.GetSinglePost(postId)
 	if err != nil {
 		return nil, err
@@ -431,7 +693,7 @@ func (a *App) PatchPost(postId string, patch *model.PostPatch) (*model.Post, *mo
 
 	post.Patch(patch)
 
-	updatedPost, err := a.UpdatePost(post, false)
+	updatedPost, err := a.UpdatePostByEditor(post, false, editorId)
 	if err != nil {
 		return nil, err
 	}
@@ -439,6 +701,20 @@ func (a *App) PatchPost(postId string, patch *model.PostPatch) (*model.Post, *mo
 	return updatedPost, nil
 }
 
+// GetPostHistory returns the earlier versions of a post, oldest first.
+func (a *App) GetPostHistory(postId string) ([]*model.PostHistory, *model.AppError) {
+	if _, err := a.GetSinglePost(postId); err != nil {
+		return nil, err
+	}
+
+	result := <-a.Srv.Store.Post().GetHistory(postId)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.([]*model.PostHistory), nil
+}
+
 func (a *App) sendUpdatedPostEvent(post *model.Post) {
 	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_POST_EDITED, "", post.ChannelId, "", nil)
 	message.Add("post", a.PostWithProxyAddedToImageURLs(post).ToJson())
@@ -576,10 +852,23 @@ func (a *App) DeletePost(postId string) (*model.Post, *model.AppError) {
 	} else {
 		post := result.Data.(*model.Post)
 
+		// deleting a root post deletes all of its replies too
+		deleted := []*model.Post{post}
+		if post.RootId == "" {
+			if result := <-a.Srv.Store.Post().Get(post.Id); result.Err == nil {
+				deleted = threadPostsFromList(result.Data.(*model.PostList))
+			}
+		}
+
 		if result := <-a.Srv.Store.Post().Delete(postId, model.GetMillis()); result.Err != nil {
 			return nil, result.Err
 		}
 
+		// this needs to happen before the thread is cleaned up so that replies counted by it are still left out
+		if err := a.removeDeletedPostsFromUnreads(post.ChannelId, deleted); err != nil {
+			mlog.Warn(fmt.Sprintf("Encountered error updating unread counts for deleted post, post_id=%v, err=%v", post.Id, err), mlog.String("post_id", post.Id))
+		}
+
 		message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_POST_DELETED, "", post.ChannelId, "", nil)
 		message.Add("post", a.PostWithProxyAddedToImageURLs(post).ToJson())
 		a.Publish(message)
@@ -590,6 +879,11 @@ func (a *App) DeletePost(postId string) (*model.Post, *model.AppError) {
 		a.Go(func() {
 			a.DeleteFlaggedPosts(post.Id)
 		})
+		a.Go(func() {
+			if err := a.updateThreadForDeletedPost(post); err != nil {
+				mlog.Warn(fmt.Sprintf("Encountered error updating thread for deleted post, post_id=%v, err=%v", post.Id, err), mlog.String("post_id", post.Id))
+			}
+		})
 
 		esInterface := a.Elasticsearch
 		if esInterface != nil && *a.Config().ElasticsearchSettings.EnableIndexing {
@@ -604,6 +898,86 @@ func (a *App) DeletePost(postId string) (*model.Post, *model.AppError) {
 	}
 }
 
+// getPostTimeLimitRoles returns the roles that apply to a user in the channel of a post when working out
+// how long they have to edit or delete it.
+func (a *App) getPostTimeLimitRoles(userId string, post *model.Post) ([]string, *model.AppError) {
+	user, err := a.GetUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := user.GetRoles()
+
+	channel, err := a.GetChannel(post.ChannelId)
+	if err != nil {
+		return nil, err
+	}
+
+	if channel.TeamId != "" {
+		if teamMember, err := a.GetTeamMember(channel.TeamId, userId); err == nil {
+			roles = append(roles, teamMember.GetRoles()...)
+		}
+	}
+
+	if channelMember, err := a.GetChannelMember(channel.Id, userId); err == nil {
+		roles = append(roles, channelMember.GetRoles()...)
+	}
+
+	return roles, nil
+}
+
+// checkPostTimeLimit returns an error if the time limit in seconds for doing something to a post created
+// at createAt has run out. The error ids passed in are for when the limit is 0 and when it has passed.
+func checkPostTimeLimit(where string, neverId string, expiredId string, limit int, createAt int64) *model.AppError {
+	if limit == -1 {
+		return nil
+	}
+
+	if limit == 0 {
+		return model.NewAppError(where, neverId, nil, "", http.StatusBadRequest)
+	}
+
+	windowEnd := createAt + int64(limit)*1000
+	if now := model.GetMillis(); now > windowEnd {
+		params := map[string]interface{}{"timeLimit": limit, "windowEnd": windowEnd}
+		return model.NewAppError(where, expiredId, params, fmt.Sprintf("window_ended=%vms ago", now-windowEnd), http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+// CheckPostEditTimeLimit returns an error if the user can no longer edit the post because the edit time
+// limit for their roles has passed.
+func (a *App) CheckPostEditTimeLimit(userId string, post *model.Post) *model.AppError {
+	if a.License() == nil {
+		return nil
+	}
+
+	roles, err := a.getPostTimeLimitRoles(userId, post)
+	if err != nil {
+		return err
+	}
+
+	limit := a.Config().ServiceSettings.GetPostEditTimeLimit(roles)
+	return checkPostTimeLimit("CheckPostEditTimeLimit", "api.post.update_post.permissions_never.app_error", "api.post.update_post.permissions_time_limit.app_error", limit, post.CreateAt)
+}
+
+// CheckPostDeleteTimeLimit returns an error if the user can no longer delete the post because the delete
+// time limit for their roles has passed.
+func (a *App) CheckPostDeleteTimeLimit(userId string, post *model.Post) *model.AppError {
+	if a.License() == nil {
+		return nil
+	}
+
+	roles, err := a.getPostTimeLimitRoles(userId, post)
+	if err != nil {
+		return err
+	}
+
+	limit := a.Config().ServiceSettings.GetPostDeleteTimeLimit(roles)
+	return checkPostTimeLimit("CheckPostDeleteTimeLimit", "api.post.delete_post.permissions_never.app_error", "api.post.delete_post.permissions_time_limit.app_error", limit, post.CreateAt)
+}
+
 func (a *App) DeleteFlaggedPosts(postId string) {
 	if result := <-a.Srv.Store.Preference().DeleteCategoryAndName(model.PREFERENCE_CATEGORY_FLAGGED_POST, postId); result.Err != nil {
 		mlog.Warn(fmt.Sprintf("Unable to delete flagged post preference when deleting post, err=%v", result.Err))
@@ -616,12 +990,35 @@ func (a *App) DeletePostFiles(post *model.Post) {
 		return
 	}
 
+	// The post has already been deleted, so the files are looked up directly rather than through it
+	var infos []*model.FileInfo
+	if result := <-a.Srv.Store.FileInfo().GetForPost(post.Id, true, false); result.Err != nil {
+		mlog.Warn(fmt.Sprintf("Unable to release the storage of the files of a deleted post, post_id=%v, err=%v", post.Id, result.Err), mlog.String("post_id", post.Id))
+	} else {
+		infos = result.Data.([]*model.FileInfo)
+		for _, info := range infos {
+			if info.ChannelId == "" {
+				info.ChannelId = post.ChannelId
+			}
+		}
+	}
+
 	if result := <-a.Srv.Store.FileInfo().DeleteForPost(post.Id); result.Err != nil {
 		mlog.Warn(fmt.Sprintf("Encountered error when deleting files for post, post_id=%v, err=%v", post.Id, result.Err), mlog.String("post_id", post.Id))
+		return
 	}
+
+	a.releaseFileStorageForFiles(infos)
 }
 
-func (a *App) SearchPostsInTeam(terms string, userId string, teamId string, isOrSearch bool) (*model.PostList, *model.AppError) {
+// SearchPostsInTeam returns a page of the posts in teamId which match terms and which userId can see, ordered
+// from newest to oldest. Posts made after searchAt are left out so that the pages of a search don't shift as new
+// posts are made; a searchAt of 0 searches the posts made up until now.
+func (a *App) SearchPostsInTeam(terms string, userId string, teamId string, isOrSearch bool, searchAt int64, page int, perPage int) (*model.PostSearchResults, *model.AppError) {
+	if searchAt == 0 {
+		searchAt = model.GetMillis()
+	}
+
 	paramsList := model.ParseSearchParams(terms)
 
 	esInterface := a.Elasticsearch
@@ -630,6 +1027,7 @@ func (a *App) SearchPostsInTeam(terms string, userId string, teamId string, isOr
 
 		for _, params := range paramsList {
 			params.OrTerms = isOrSearch
+			params.MaxCreateAt = searchAt
 			// Don't allow users to search for "*"
 			if params.Terms != "*" {
 				// Convert channel names to channel IDs
@@ -656,7 +1054,7 @@ func (a *App) SearchPostsInTeam(terms string, userId string, teamId string, isOr
 
 		// If the processed search params are empty, return empty search results.
 		if len(finalParamsList) == 0 {
-			return model.NewPostList(), nil
+			return &model.PostSearchResults{PostList: model.NewPostList(), SearchAt: searchAt}, nil
 		}
 
 		// We only allow the user to search in channels they are a member of.
@@ -666,7 +1064,7 @@ func (a *App) SearchPostsInTeam(terms string, userId string, teamId string, isOr
 			return nil, err
 		}
 
-		postIds, err := a.Elasticsearch.SearchPosts(userChannels, finalParamsList)
+		postIds, totalCount, err := a.Elasticsearch.SearchPosts(userChannels, finalParamsList, page, perPage)
 		if err != nil {
 			return nil, err
 		}
@@ -684,38 +1082,74 @@ func (a *App) SearchPostsInTeam(terms string, userId string, teamId string, isOr
 			}
 		}
 
-		return postList, nil
+		postList.SortByCreateAt()
+
+		return &model.PostSearchResults{PostList: postList, TotalCount: totalCount, SearchAt: searchAt}, nil
 	} else {
 		if !*a.Config().ServiceSettings.EnablePostSearch {
 			return nil, model.NewAppError("SearchPostsInTeam", "store.sql_post.search.disabled", nil, fmt.Sprintf("teamId=%v userId=%v", teamId, userId), http.StatusNotImplemented)
 		}
 
+		// The database can't page through the merged results of several searches, so each search returns
+		// enough posts to fill the pages up to the one asked for, plus one to tell whether there are more.
+		limit := (page + 1) * perPage
+		if limit > model.POST_SEARCH_MAX_RESULTS {
+			limit = model.POST_SEARCH_MAX_RESULTS
+		}
+		limit++
+
 		channels := []store.StoreChannel{}
 
 		for _, params := range paramsList {
 			params.OrTerms = isOrSearch
+			params.MaxCreateAt = searchAt
+			params.Limit = limit
 			// don't allow users to search for everything
 			if params.Terms != "*" {
 				channels = append(channels, a.Srv.Store.Post().Search(teamId, userId, params))
 			}
 		}
 
+		isApproximate := false
 		posts := model.NewPostList()
 		for _, channel := range channels {
 			if result := <-channel; result.Err != nil {
 				return nil, result.Err
 			} else {
 				data := result.Data.(*model.PostList)
+				// a search that filled its limit may have had more matches which weren't counted
+				if len(data.Order) >= limit {
+					isApproximate = true
+				}
 				posts.Extend(data)
 			}
 		}
 
 		posts.SortByCreateAt()
 
-		return posts, nil
+		return &model.PostSearchResults{
+			PostList:      getPostListPage(posts, page, perPage),
+			TotalCount:    int64(len(posts.Order)),
+			IsApproximate: isApproximate,
+			SearchAt:      searchAt,
+		}, nil
 	}
 }
 
+// getPostListPage returns the posts on a page of an ordered post list.
+func getPostListPage(list *model.PostList, page int, perPage int) *model.PostList {
+	pageList := model.NewPostList()
+
+	start := page * perPage
+	for i := start; i < start+perPage && i < len(list.Order); i++ {
+		id := list.Order[i]
+		pageList.AddPost(list.Posts[id])
+		pageList.AddOrder(id)
+	}
+
+	return pageList
+}
+
 func (a *App) GetFileInfosForPost(postId string, readFromMaster bool) ([]*model.FileInfo, *model.AppError) {
 	pchan := a.Srv.Store.Post().GetSingle(postId)
 	fchan := a.Srv.Store.FileInfo().GetForPost(postId, readFromMaster, true)
@@ -746,28 +1180,6 @@ func (a *App) GetFileInfosForPost(postId string, readFromMaster bool) ([]*model.
 	return infos, nil
 }
 
-func (a *App) GetOpenGraphMetadata(requestURL string) *opengraph.OpenGraph {
-	og := opengraph.NewOpenGraph()
-
-	res, err := a.HTTPClient(false).Get(requestURL)
-	if err != nil {
-		mlog.Error(fmt.Sprintf("GetOpenGraphMetadata request failed for url=%v with err=%v", requestURL, err.Error()))
-		return og
-	}
-	defer consumeAndClose(res)
-
-	contentType := res.Header.Get("Content-Type")
-	body := forceHTMLEncodingToUTF8(res.Body, contentType)
-
-	if err := og.ProcessHTML(body); err != nil {
-		mlog.Error(fmt.Sprintf("GetOpenGraphMetadata processing failed for url=%v with err=%v", requestURL, err.Error()))
-	}
-
-	makeOpenGraphURLsAbsolute(og, requestURL)
-
-	return og
-}
-
 func forceHTMLEncodingToUTF8(body io.Reader, contentType string) io.Reader {
 	r, err := charset.NewReader(body, contentType)
 	if err != nil {
@@ -820,6 +1232,12 @@ func makeOpenGraphURLsAbsolute(og *opengraph.OpenGraph, requestURL string) {
 	}
 }
 
+// GetPostActionSigningSecret returns the secret that the requests to the post action integration at integrationURL
+// are signed with, which its owner needs to verify them.
+func (a *App) GetPostActionSigningSecret(integrationURL string) (string, *model.AppError) {
+	return model.PostActionSigningSecretForURL(*a.Config().ServiceSettings.PostActionSigningSecret, integrationURL)
+}
+
 func (a *App) DoPostAction(postId string, actionId string, userId string) *model.AppError {
 	pchan := a.Srv.Store.Post().GetSingle(postId)
 
@@ -835,17 +1253,40 @@ func (a *App) DoPostAction(postId string, actionId string, userId string) *model
 		return model.NewAppError("DoPostAction", "api.post.do_action.action_id.app_error", nil, fmt.Sprintf("action=%v", action), http.StatusNotFound)
 	}
 
+	if action.Disabled {
+		return model.NewAppError("DoPostAction", "api.post.do_action.disabled.app_error", nil, "action_id="+actionId, http.StatusBadRequest)
+	}
+
+	channel, err := a.GetChannel(post.ChannelId)
+	if err != nil {
+		return err
+	}
+
 	request := &model.PostActionIntegrationRequest{
-		UserId:  userId,
-		Context: action.Integration.Context,
+		UserId:    userId,
+		PostId:    post.Id,
+		ChannelId: post.ChannelId,
+		TeamId:    channel.TeamId,
+		ActionId:  actionId,
+		Context:   action.Integration.Context,
+		RequestId: model.NewId(),
+		Timestamp: model.GetMillis(),
 	}
 
-	req, _ := http.NewRequest("POST", action.Integration.URL, strings.NewReader(request.ToJson()))
+	body := []byte(request.ToJson())
+
+	secret, err := a.GetPostActionSigningSecret(action.Integration.URL)
+	if err != nil {
+		return err
+	}
+
+	req, _ := http.NewRequest("POST", action.Integration.URL, bytes.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
-	resp, err := a.HTTPClient(false).Do(req)
-	if err != nil {
-		return model.NewAppError("DoPostAction", "api.post.do_action.action_integration.app_error", nil, "err="+err.Error(), http.StatusBadRequest)
+	req.Header.Set(model.HEADER_POST_ACTION_SIGNATURE, model.SignPostActionIntegrationRequest(body, secret))
+	resp, httpErr := a.HTTPClient(false).Do(req)
+	if httpErr != nil {
+		return model.NewAppError("DoPostAction", "api.post.do_action.action_integration.app_error", nil, "err="+httpErr.Error(), http.StatusBadRequest)
 	}
 	defer consumeAndClose(resp)
 
@@ -860,17 +1301,8 @@ func (a *App) DoPostAction(postId string, actionId string, userId string) *model
 
 	retainedProps := []string{"override_username", "override_icon_url"}
 
-	if response.Update != nil {
-		response.Update.Id = postId
-		response.Update.AddProp("from_webhook", "true")
-		for _, prop := range retainedProps {
-			if value, ok := post.Props[prop]; ok {
-				response.Update.Props[prop] = value
-			} else {
-				delete(response.Update.Props, prop)
-			}
-		}
-		if _, err := a.UpdatePost(response.Update, false); err != nil {
+	if update := postActionUpdate(post, &response, retainedProps); update != nil {
+		if _, err := a.UpdatePost(update, false); err != nil {
 			return err
 		}
 	}
@@ -898,6 +1330,49 @@ func (a *App) DoPostAction(postId string, actionId string, userId string) *model
 	return nil
 }
 
+// postActionUpdate returns the post as it should be after an integration responds to one of its actions, or nil if
+// the response doesn't change it. The post is either replaced by the response's update or has the response's props
+// set on it, and then has any actions that the response disables disabled.
+func postActionUpdate(post *model.Post, response *model.PostActionIntegrationResponse, retainedProps []string) *model.Post {
+	var update *model.Post
+
+	if response.Update != nil {
+		update = response.Update
+		update.Id = post.Id
+	} else if len(response.Props) > 0 || len(response.DisableActions) > 0 {
+		update = &model.Post{}
+		*update = *post
+		update.Props = make(model.StringInterface, len(post.Props)+len(response.Props))
+		for key, value := range post.Props {
+			update.Props[key] = value
+		}
+		for key, value := range response.Props {
+			if value == nil {
+				delete(update.Props, key)
+			} else {
+				update.Props[key] = value
+			}
+		}
+	} else {
+		return nil
+	}
+
+	update.AddProp("from_webhook", "true")
+	for _, prop := range retainedProps {
+		if value, ok := post.Props[prop]; ok {
+			update.Props[prop] = value
+		} else {
+			delete(update.Props, prop)
+		}
+	}
+
+	// New actions need ids before they can be disabled or performed
+	update.GenerateActionIds()
+	update.DisableActions(response.DisableActions)
+
+	return update
+}
+
 func (a *App) PostListWithProxyAddedToImageURLs(list *model.PostList) *model.PostList {
 	if f := a.ImageProxyAdder(); f != nil {
 		return list.WithRewrittenImageURLs(f)
@@ -937,11 +1412,16 @@ func (a *App) imageProxyConfig() (proxyType, proxyURL, options, siteURL string)
 	proxyType = *cfg.ServiceSettings.ImageProxyType
 	siteURL = *cfg.ServiceSettings.SiteURL
 
+	if proxyType == model.IMAGE_PROXY_TYPE_LOCAL {
+		// The local proxy is served by this server, so the configured URL is ignored.
+		proxyURL = strings.TrimRight(siteURL, "/") + model.API_URL_SUFFIX + "/image"
+	}
+
 	if proxyURL == "" || proxyType == "" {
 		return "", "", "", ""
 	}
 
-	if proxyURL[len(proxyURL)-1] != '/' {
+	if proxyURL[len(proxyURL)-1] != '/' && proxyType != model.IMAGE_PROXY_TYPE_LOCAL {
 		proxyURL += "/"
 	}
 
@@ -968,11 +1448,13 @@ func (a *App) ImageProxyAdder() func(string) string {
 		}
 
 		switch proxyType {
-		case "atmos/camo":
+		case model.IMAGE_PROXY_TYPE_ATMOS_CAMO:
 			mac := hmac.New(sha1.New, []byte(options))
 			mac.Write([]byte(url))
 			digest := hex.EncodeToString(mac.Sum(nil))
 			return proxyURL + digest + "/" + hex.EncodeToString([]byte(url))
+		case model.IMAGE_PROXY_TYPE_LOCAL:
+			return a.localImageProxyURL(proxyURL, url)
 		}
 
 		return url
@@ -987,7 +1469,7 @@ func (a *App) ImageProxyRemover() (f func(string) string) {
 
 	return func(url string) string {
 		switch proxyType {
-		case "atmos/camo":
+		case model.IMAGE_PROXY_TYPE_ATMOS_CAMO:
 			if strings.HasPrefix(url, proxyURL) {
 				if slash := strings.IndexByte(url[len(proxyURL):], '/'); slash >= 0 {
 					if decoded, err := hex.DecodeString(url[len(proxyURL)+slash+1:]); err == nil {
@@ -995,6 +1477,10 @@ func (a *App) ImageProxyRemover() (f func(string) string) {
 					}
 				}
 			}
+		case model.IMAGE_PROXY_TYPE_LOCAL:
+			if imageURL := localImageProxyTarget(proxyURL, url); imageURL != "" {
+				return imageURL
+			}
 		}
 
 		return url
@@ -1011,3 +1497,26 @@ func (a *App) MaxPostSize() int {
 
 	return maxPostSize
 }
+
+// FOUR_BYTE_CHARACTERS_WARNING_INTERVAL is how often the server logs that posts with emoji in them can't be saved
+// properly by the database.
+const FOUR_BYTE_CHARACTERS_WARNING_INTERVAL = 10 * 60 * 1000
+
+// warnAboutFourByteCharacters logs that a post has characters in it that the database can't store, such as emoji on
+// MySQL databases that use utf8 instead of utf8mb4, at most once every FOUR_BYTE_CHARACTERS_WARNING_INTERVAL.
+func (a *App) warnAboutFourByteCharacters(post *model.Post) {
+	if a.Srv.Store.IsFullUnicodeSupported() || !model.HasFourByteCharacters(post.Message) {
+		return
+	}
+
+	a.fourByteCharactersWarningLock.Lock()
+	defer a.fourByteCharactersWarningLock.Unlock()
+
+	now := model.GetMillis()
+	if now-a.lastFourByteCharactersWarning < FOUR_BYTE_CHARACTERS_WARNING_INTERVAL {
+		return
+	}
+	a.lastFourByteCharactersWarning = now
+
+	mlog.Warn("A post with emoji in it is being saved, but the database uses the utf8 charset, so it will be rejected or mangled. Run \"mattermost db migrate-charset --to utf8mb4\" to convert the database.", mlog.String("channel_id", post.ChannelId))
+}
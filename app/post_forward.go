@@ -0,0 +1,162 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const FORWARD_AUDIENCE_PAGE_SIZE = 100
+
+// ForwardPost shares a post in another channel as a new post by userId. The new post carries a preview of
+// the original in its props, unless some of the channel it's shared to can't read the original, in which
+// case only a link to it is shared. Callers are expected to have checked that the user can read the
+// original post and post in the channel.
+func (a *App) ForwardPost(postId string, forward *model.PostForward, userId string) (*model.Post, *model.AppError) {
+	original, err := a.GetSinglePost(postId)
+	if err != nil {
+		return nil, err
+	}
+
+	if original.IsSystemMessage() {
+		return nil, model.NewAppError("ForwardPost", "app.post.forward.system_message.app_error", nil, "id="+postId, http.StatusBadRequest)
+	}
+
+	sourceChannel, err := a.GetChannel(original.ChannelId)
+	if err != nil {
+		return nil, err
+	}
+
+	targetChannel, err := a.GetChannel(forward.ChannelId)
+	if err != nil {
+		return nil, err
+	}
+
+	permalink, err := a.getForwardedPostPermalink(original, sourceChannel, targetChannel, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	canRead, err := a.channelAudienceCanReadChannel(targetChannel, sourceChannel)
+	if err != nil {
+		return nil, err
+	}
+
+	post := &model.Post{
+		ChannelId: targetChannel.Id,
+		UserId:    userId,
+		Message:   forward.Message,
+	}
+
+	var preview *model.ForwardedPostPreview
+	if canRead {
+		author, err := a.GetUser(original.UserId)
+		if err != nil {
+			return nil, err
+		}
+
+		preview = model.NewForwardedPostPreview(original, author, sourceChannel, permalink)
+	} else {
+		preview = &model.ForwardedPostPreview{PostId: original.Id, Permalink: permalink}
+		post.Message = strings.TrimSpace(post.Message + "\n" + permalink)
+	}
+
+	// Clients can't set the preview themselves, so it's added after the post's props have been sanitized
+	return a.createPostAsUser(post, model.StringInterface{model.POST_PROPS_FORWARDED_POST: preview.ToMap()})
+}
+
+// getForwardedPostPermalink returns the permalink to a post. Posts in direct and group messages don't
+// belong to a team, so the team of the channel the post is forwarded to, or else one of the forwarder's
+// teams, is used for those.
+func (a *App) getForwardedPostPermalink(post *model.Post, sourceChannel, targetChannel *model.Channel, userId string) (string, *model.AppError) {
+	var team *model.Team
+	var err *model.AppError
+
+	if sourceChannel.TeamId != "" {
+		team, err = a.GetTeam(sourceChannel.TeamId)
+	} else if targetChannel.TeamId != "" {
+		team, err = a.GetTeam(targetChannel.TeamId)
+	} else {
+		var teams []*model.Team
+		if teams, err = a.GetTeamsForUser(userId); err == nil {
+			if len(teams) == 0 {
+				return "", model.NewAppError("ForwardPost", "app.post.forward.no_team.app_error", nil, "", http.StatusBadRequest)
+			}
+			team = teams[0]
+		}
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return a.GetSiteURL() + "/" + team.Name + "/pl/" + post.Id, nil
+}
+
+// channelAudienceCanReadChannel returns true if every member of audience is able to read the posts in
+// channel, either because they're a member of it or because it's a public channel on one of their teams.
+// Guests can only ever read the channels they're members of.
+func (a *App) channelAudienceCanReadChannel(audience *model.Channel, channel *model.Channel) (bool, *model.AppError) {
+	if audience.Id == channel.Id {
+		return true, nil
+	}
+
+	for offset := 0; ; offset += FORWARD_AUDIENCE_PAGE_SIZE {
+		result := <-a.Srv.Store.User().GetProfilesInChannel(audience.Id, offset, FORWARD_AUDIENCE_PAGE_SIZE)
+		if result.Err != nil {
+			return false, result.Err
+		}
+		users := result.Data.([]*model.User)
+
+		if len(users) == 0 {
+			return true, nil
+		}
+
+		userIds := make([]string, 0, len(users))
+		for _, user := range users {
+			userIds = append(userIds, user.Id)
+		}
+
+		result = <-a.Srv.Store.Channel().GetMembersByIds(channel.Id, userIds)
+		if result.Err != nil {
+			return false, result.Err
+		}
+
+		channelMembers := make(map[string]bool)
+		for _, member := range *result.Data.(*model.ChannelMembers) {
+			channelMembers[member.UserId] = true
+		}
+
+		teamMembers := make(map[string]bool)
+		if channel.Type == model.CHANNEL_OPEN {
+			result = <-a.Srv.Store.Team().GetMembersByIds(channel.TeamId, userIds)
+			if result.Err != nil {
+				return false, result.Err
+			}
+
+			for _, member := range result.Data.([]*model.TeamMember) {
+				teamMembers[member.UserId] = true
+			}
+		}
+
+		for _, user := range users {
+			if channelMembers[user.Id] {
+				continue
+			}
+
+			if teamMembers[user.Id] && !user.IsGuest() {
+				continue
+			}
+
+			return false, nil
+		}
+
+		if len(users) < FORWARD_AUDIENCE_PAGE_SIZE {
+			return true, nil
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestForwardPost(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	target := th.CreateChannel(th.BasicTeam)
+	th.AddUserToChannel(th.BasicUser2, target)
+
+	private := th.createChannel(th.BasicTeam, model.CHANNEL_PRIVATE)
+	privatePost := th.CreatePost(private)
+
+	permalink := th.App.GetSiteURL() + "/" + th.BasicTeam.Name + "/pl/" + privatePost.Id
+
+	t.Run("private to public is degraded to a link", func(t *testing.T) {
+		post, err := th.App.ForwardPost(privatePost.Id, &model.PostForward{ChannelId: target.Id, Message: "have a look"}, th.BasicUser.Id)
+		require.Nil(t, err)
+
+		assert.Equal(t, target.Id, post.ChannelId)
+		assert.Equal(t, th.BasicUser.Id, post.UserId)
+		assert.Equal(t, "have a look\n"+permalink, post.Message)
+
+		preview := post.Props[model.POST_PROPS_FORWARDED_POST].(map[string]interface{})
+		assert.Equal(t, map[string]interface{}{"post_id": privatePost.Id, "permalink": permalink}, preview)
+	})
+
+	t.Run("private to public when everyone can read the original", func(t *testing.T) {
+		th.AddUserToChannel(th.BasicUser2, private)
+
+		post, err := th.App.ForwardPost(privatePost.Id, &model.PostForward{ChannelId: target.Id, Message: "have a look"}, th.BasicUser.Id)
+		require.Nil(t, err)
+
+		assert.Equal(t, "have a look", post.Message)
+
+		preview := post.Props[model.POST_PROPS_FORWARDED_POST].(map[string]interface{})
+		assert.Equal(t, privatePost.Id, preview["post_id"])
+		assert.Equal(t, permalink, preview["permalink"])
+		assert.Equal(t, th.BasicUser.Id, preview["user_id"])
+		assert.Equal(t, th.BasicUser.Username, preview["username"])
+		assert.Equal(t, private.Id, preview["channel_id"])
+		assert.Equal(t, private.DisplayName, preview["channel_display_name"])
+		assert.EqualValues(t, privatePost.CreateAt, preview["create_at"])
+		assert.Equal(t, privatePost.Message, preview["excerpt"])
+
+		// the preview survives being read back from the database
+		saved, err := th.App.GetSinglePost(post.Id)
+		require.Nil(t, err)
+		assert.Equal(t, th.BasicUser.Username, saved.Props[model.POST_PROPS_FORWARDED_POST].(map[string]interface{})["username"])
+	})
+
+	t.Run("public to public", func(t *testing.T) {
+		public := th.CreateChannel(th.BasicTeam)
+		publicPost := th.CreatePost(public)
+
+		post, err := th.App.ForwardPost(publicPost.Id, &model.PostForward{ChannelId: target.Id}, th.BasicUser.Id)
+		require.Nil(t, err)
+
+		preview := post.Props[model.POST_PROPS_FORWARDED_POST].(map[string]interface{})
+		assert.Equal(t, th.BasicUser.Username, preview["username"])
+		assert.Equal(t, "", post.Message)
+
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.GuestAccountsSettings.Enable = true })
+
+		guest := th.CreateUser()
+		th.LinkUserToTeam(guest, th.BasicTeam)
+		th.AddUserToChannel(guest, target)
+		_, err = th.App.DemoteUserToGuest(guest)
+		require.Nil(t, err)
+
+		post, err = th.App.ForwardPost(publicPost.Id, &model.PostForward{ChannelId: target.Id}, th.BasicUser.Id)
+		require.Nil(t, err)
+
+		preview = post.Props[model.POST_PROPS_FORWARDED_POST].(map[string]interface{})
+		assert.Len(t, preview, 2)
+		assert.Equal(t, th.App.GetSiteURL()+"/"+th.BasicTeam.Name+"/pl/"+publicPost.Id, post.Message)
+	})
+
+	t.Run("long messages are shortened", func(t *testing.T) {
+		long := &model.Post{ChannelId: private.Id, UserId: th.BasicUser.Id, Message: model.NewRandomString(model.POST_FORWARD_EXCERPT_MAX_RUNES + 10)}
+		long, err := th.App.CreatePost(long, private, false)
+		require.Nil(t, err)
+
+		post, err := th.App.ForwardPost(long.Id, &model.PostForward{ChannelId: target.Id}, th.BasicUser.Id)
+		require.Nil(t, err)
+
+		excerpt := post.Props[model.POST_PROPS_FORWARDED_POST].(map[string]interface{})["excerpt"].(string)
+		assert.Len(t, []rune(excerpt), model.POST_FORWARD_EXCERPT_MAX_RUNES)
+	})
+}
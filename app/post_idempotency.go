@@ -0,0 +1,137 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const (
+	POST_IDEMPOTENCY_POLL_INTERVAL = 50 * time.Millisecond
+	POST_IDEMPOTENCY_WAIT_TIMEOUT  = 10 * time.Second
+	POST_IDEMPOTENCY_BATCH_SIZE    = 10000
+)
+
+// CreatePostAsUserIdempotent creates the post like CreatePostAsUser, except that the pending post id sent by the
+// client is used as an idempotency key: if the user already created a post with it in the last
+// ServiceSettings.PostIdempotencyWindowSeconds, that post is returned instead of creating another one. This keeps
+// clients that retry a request after a dropped connection from posting the same message twice. created is false if
+// an existing post was returned.
+func (a *App) CreatePostAsUserIdempotent(post *model.Post) (*model.Post, bool, *model.AppError) {
+	window := *a.Config().ServiceSettings.PostIdempotencyWindowSeconds
+	if window == 0 || post.PendingPostId == "" {
+		rp, err := a.CreatePostAsUser(post)
+		return rp, err == nil, err
+	}
+
+	if len(post.PendingPostId) > model.POST_IDEMPOTENCY_KEY_MAX_LENGTH {
+		return nil, false, model.NewAppError("CreatePostAsUserIdempotent", "model.post_idempotency_key.is_valid.idempotency_key.app_error", map[string]interface{}{"MaxLength": model.POST_IDEMPOTENCY_KEY_MAX_LENGTH}, "user_id="+post.UserId, http.StatusBadRequest)
+	}
+
+	cacheKey := post.UserId + ":" + post.PendingPostId
+	if postId, ok := a.postIdempotencyCache.Get(cacheKey); ok {
+		if rp, err := a.getIdempotentPost(postId.(string), post.PendingPostId); err == nil {
+			return rp, false, nil
+		}
+	}
+
+	key := &model.PostIdempotencyKey{
+		UserId:         post.UserId,
+		IdempotencyKey: post.PendingPostId,
+	}
+	expiredBefore := model.GetMillis() - int64(window)*1000
+
+	result := <-a.Srv.Store.PostIdempotencyKey().Reserve(key, expiredBefore)
+	if result.Err != nil {
+		return nil, false, result.Err
+	}
+
+	if !result.Data.(bool) {
+		// Another request with the same key got there first, so wait for it to create the post
+		rp, err := a.waitForIdempotentPost(post.UserId, post.PendingPostId)
+		if err != nil {
+			return nil, false, err
+		}
+
+		a.postIdempotencyCache.AddWithExpiresInSecs(cacheKey, rp.Id, int64(window))
+		return rp, false, nil
+	}
+
+	rp, err := a.CreatePostAsUser(post)
+	if err != nil {
+		// Let the client retry with the same key
+		if result := <-a.Srv.Store.PostIdempotencyKey().Delete(post.UserId, post.PendingPostId); result.Err != nil {
+			mlog.Error(fmt.Sprintf("Failed to delete a pending post id after the post couldn't be created, user_id=%v, err=%v", post.UserId, result.Err.Error()))
+		}
+		return nil, false, err
+	}
+
+	if result := <-a.Srv.Store.PostIdempotencyKey().SetPostId(post.UserId, post.PendingPostId, rp.Id); result.Err != nil {
+		mlog.Error(fmt.Sprintf("Failed to save the post created for a pending post id, user_id=%v, post_id=%v, err=%v", post.UserId, rp.Id, result.Err.Error()))
+	}
+	a.postIdempotencyCache.AddWithExpiresInSecs(cacheKey, rp.Id, int64(window))
+
+	return rp, true, nil
+}
+
+// getIdempotentPost returns the post created for a pending post id, with the pending post id set like it is on the
+// post returned when it's created, so that the client can match it to the message that it sent.
+func (a *App) getIdempotentPost(postId, pendingPostId string) (*model.Post, *model.AppError) {
+	rp, err := a.GetSinglePost(postId)
+	if err != nil {
+		return nil, err
+	}
+
+	rp.PendingPostId = pendingPostId
+	return rp, nil
+}
+
+// waitForIdempotentPost waits for the request that reserved the pending post id to create its post, returning a
+// conflict if it doesn't within POST_IDEMPOTENCY_WAIT_TIMEOUT or fails to create it.
+func (a *App) waitForIdempotentPost(userId, pendingPostId string) (*model.Post, *model.AppError) {
+	deadline := time.Now().Add(POST_IDEMPOTENCY_WAIT_TIMEOUT)
+
+	for {
+		result := <-a.Srv.Store.PostIdempotencyKey().Get(userId, pendingPostId)
+		if result.Err != nil && result.Err.StatusCode != http.StatusNotFound {
+			return nil, result.Err
+		} else if result.Err != nil {
+			// The other request failed and deleted the key
+			break
+		}
+
+		if key := result.Data.(*model.PostIdempotencyKey); key.PostId != "" {
+			return a.getIdempotentPost(key.PostId, pendingPostId)
+		}
+
+		if time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(POST_IDEMPOTENCY_POLL_INTERVAL)
+	}
+
+	return nil, model.NewAppError("CreatePostAsUserIdempotent", "app.post.create_post.idempotency_conflict.app_error", nil, "user_id="+userId, http.StatusConflict)
+}
+
+// CleanupPostIdempotencyKeys deletes the pending post ids that are past ServiceSettings.PostIdempotencyWindowSeconds.
+func (a *App) CleanupPostIdempotencyKeys() {
+	endTime := model.GetMillis() - int64(*a.Config().ServiceSettings.PostIdempotencyWindowSeconds)*1000
+
+	for {
+		result := <-a.Srv.Store.PostIdempotencyKey().PermanentDeleteBatch(endTime, POST_IDEMPOTENCY_BATCH_SIZE)
+		if result.Err != nil {
+			mlog.Error(fmt.Sprintf("Failed to delete old pending post ids err=%v", result.Err.Error()))
+			return
+		}
+
+		if result.Data.(int64) < POST_IDEMPOTENCY_BATCH_SIZE {
+			return
+		}
+	}
+}
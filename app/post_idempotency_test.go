@@ -0,0 +1,124 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestCreatePostAsUserIdempotent(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	newPost := func(pendingPostId string) *model.Post {
+		return &model.Post{
+			ChannelId:     th.BasicChannel.Id,
+			UserId:        th.BasicUser.Id,
+			Message:       "message " + pendingPostId,
+			PendingPostId: pendingPostId,
+		}
+	}
+
+	t.Run("retry", func(t *testing.T) {
+		pendingPostId := model.NewId() + ":" + "1"
+
+		rpost, created, err := th.App.CreatePostAsUserIdempotent(newPost(pendingPostId))
+		require.Nil(t, err)
+		assert.True(t, created)
+
+		retried, created, err := th.App.CreatePostAsUserIdempotent(newPost(pendingPostId))
+		require.Nil(t, err)
+		assert.False(t, created)
+		assert.Equal(t, rpost.Id, retried.Id)
+		assert.Equal(t, pendingPostId, retried.PendingPostId)
+
+		// Without the cache, the post should still be found from the saved key
+		th.App.postIdempotencyCache.Purge()
+		retried, created, err = th.App.CreatePostAsUserIdempotent(newPost(pendingPostId))
+		require.Nil(t, err)
+		assert.False(t, created)
+		assert.Equal(t, rpost.Id, retried.Id)
+
+		other, created, err := th.App.CreatePostAsUserIdempotent(newPost(model.NewId()))
+		require.Nil(t, err)
+		assert.True(t, created)
+		assert.NotEqual(t, rpost.Id, other.Id)
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		pendingPostId := model.NewId() + ":" + "2"
+
+		var wg sync.WaitGroup
+		ids := make([]string, 5)
+		createdBy := make([]bool, 5)
+		for i := range ids {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+
+				rpost, created, err := th.App.CreatePostAsUserIdempotent(newPost(pendingPostId))
+				if assert.Nil(t, err) {
+					ids[i] = rpost.Id
+					createdBy[i] = created
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		created := 0
+		for i := range ids {
+			assert.Equal(t, ids[0], ids[i], "every request should get the same post")
+			if createdBy[i] {
+				created++
+			}
+		}
+		assert.Equal(t, 1, created, "only one request should create the post")
+
+		posts, err := th.App.GetPosts(th.BasicChannel.Id, 0, 100)
+		require.Nil(t, err)
+		count := 0
+		for _, post := range posts.Posts {
+			if post.Message == "message "+pendingPostId {
+				count++
+			}
+		}
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("failed create", func(t *testing.T) {
+		pendingPostId := model.NewId() + ":" + "3"
+
+		post := newPost(pendingPostId)
+		post.ChannelId = model.NewId()
+		_, created, err := th.App.CreatePostAsUserIdempotent(post)
+		require.NotNil(t, err)
+		assert.False(t, created)
+
+		_, created, err = th.App.CreatePostAsUserIdempotent(newPost(pendingPostId))
+		require.Nil(t, err)
+		assert.True(t, created, "a key should be released if its post couldn't be created")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.PostIdempotencyWindowSeconds = 0 })
+		defer th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.PostIdempotencyWindowSeconds = 300 })
+
+		pendingPostId := model.NewId() + ":" + "4"
+
+		rpost, created, err := th.App.CreatePostAsUserIdempotent(newPost(pendingPostId))
+		require.Nil(t, err)
+		assert.True(t, created)
+
+		other, created, err := th.App.CreatePostAsUserIdempotent(newPost(pendingPostId))
+		require.Nil(t, err)
+		assert.True(t, created)
+		assert.NotEqual(t, rpost.Id, other.Id)
+	})
+}
@@ -0,0 +1,424 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/dyatlov/go-opengraph/opengraph"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// PostWithMetadata returns a copy of post with the metadata that depends on userId, which is previews of the
+// posts that it links to and a summary of its reactions, along with its text in userId's language if it's a system
+// message.
+func (a *App) PostWithMetadata(post *model.Post, userId string) *model.Post {
+	list := model.NewPostList()
+	list.AddPost(post)
+
+	return a.PostListWithMetadata(list, userId).Posts[post.Id]
+}
+
+// PostListWithMetadata is like PostWithMetadata, but for every post in list.
+func (a *App) PostListWithMetadata(list *model.PostList, userId string) *model.PostList {
+	return a.PostListWithLocalizedSystemMessages(a.PostListWithReactionSummaries(a.PostListWithPermalinkPreviews(list, userId), userId), userId)
+}
+
+// PostWithExtendedMetadata is like PostWithMetadata, but also includes the file infos attached to the post, the
+// custom emojis that it uses and an embed for the first link in it.
+func (a *App) PostWithExtendedMetadata(post *model.Post, userId string) *model.Post {
+	list := model.NewPostList()
+	list.AddPost(post)
+
+	return a.PostListWithExtendedMetadata(list, userId).Posts[post.Id]
+}
+
+// PostListWithExtendedMetadata is like PostWithExtendedMetadata, but for every post in list. Each kind of
+// metadata is loaded for the whole list at once, so the number of queries doesn't grow with the number of posts.
+// It's only sent to clients that ask for it since it makes posts much larger.
+func (a *App) PostListWithExtendedMetadata(list *model.PostList, userId string) *model.PostList {
+	list = a.PostListWithMetadata(list, userId)
+
+	files := a.getFileInfosForPosts(list)
+	emojis := a.getCustomEmojisForPosts(list)
+	links := a.getLinkEmbedsForPosts(list)
+
+	copy := *list
+	copy.Posts = make(map[string]*model.Post, len(list.Posts))
+	for id, post := range list.Posts {
+		if len(files[id]) == 0 && len(emojis[id]) == 0 && links[id] == nil {
+			copy.Posts[id] = post
+			continue
+		}
+
+		metadata := &model.PostMetadata{}
+		if post.Metadata != nil {
+			*metadata = *post.Metadata
+		}
+		metadata.Files = files[id]
+		metadata.Emojis = emojis[id]
+		if link := links[id]; link != nil {
+			metadata.Embeds = append(append([]*model.PostEmbed{}, metadata.Embeds...), link)
+		}
+
+		pcopy := *post
+		pcopy.Metadata = metadata
+		copy.Posts[id] = &pcopy
+	}
+
+	return &copy
+}
+
+// PostWithPermalinkPreviews returns a copy of post with previews of the posts that it links to which userId is
+// allowed to read.
+func (a *App) PostWithPermalinkPreviews(post *model.Post, userId string) *model.Post {
+	list := model.NewPostList()
+	list.AddPost(post)
+
+	return a.PostListWithPermalinkPreviews(list, userId).Posts[post.Id]
+}
+
+// PostListWithPermalinkPreviews returns a copy of list where each post has previews of the posts that it links
+// to which userId is allowed to read. Whether a user can read a linked post is checked every time that posts are
+// fetched since it may change after the post was made, and links to posts that have been deleted or can't be
+// read are left without a preview.
+func (a *App) PostListWithPermalinkPreviews(list *model.PostList, userId string) *model.PostList {
+	if !*a.Config().ServiceSettings.EnablePermalinkPreviews {
+		return list
+	}
+
+	siteURL := a.GetSiteURL()
+
+	permalinksByPost := make(map[string][]*model.PostPermalink)
+	var linkedPostIds []string
+	for id, post := range list.Posts {
+		permalinks := model.FindPostPermalinks(post.Message, siteURL)
+		if len(permalinks) == 0 {
+			continue
+		}
+
+		permalinksByPost[id] = permalinks
+		for _, permalink := range permalinks {
+			linkedPostIds = append(linkedPostIds, permalink.PostId)
+		}
+	}
+
+	if len(linkedPostIds) == 0 {
+		return list
+	}
+
+	previews := a.getPermalinkPreviews(linkedPostIds, userId)
+
+	copy := *list
+	copy.Posts = make(map[string]*model.Post, len(list.Posts))
+	for id, post := range list.Posts {
+		var embeds []*model.PostEmbed
+		for _, permalink := range permalinksByPost[id] {
+			if preview, ok := previews[permalink.PostId]; ok {
+				embeds = append(embeds, &model.PostEmbed{Type: model.POST_EMBED_PERMALINK, URL: permalink.URL, Data: preview})
+			}
+		}
+
+		if len(embeds) == 0 {
+			copy.Posts[id] = post
+			continue
+		}
+
+		pcopy := *post
+		pcopy.Metadata = &model.PostMetadata{Embeds: embeds}
+		copy.Posts[id] = &pcopy
+	}
+
+	return &copy
+}
+
+// PostListWithReactionSummaries returns a copy of list where each post that has reactions has a count of them
+// for each emoji, along with whether userId is one of the users who reacted with it. Sending only the counts
+// keeps the size of posts with many reactions small.
+func (a *App) PostListWithReactionSummaries(list *model.PostList, userId string) *model.PostList {
+	var postIds []string
+	for id, post := range list.Posts {
+		if post.HasReactions {
+			postIds = append(postIds, id)
+		}
+	}
+
+	if len(postIds) == 0 {
+		return list
+	}
+
+	result := <-a.Srv.Store.Reaction().GetCountsForPosts(postIds, userId)
+	if result.Err != nil {
+		mlog.Warn(fmt.Sprintf("Unable to get reaction counts for posts, err=%v", result.Err))
+		return list
+	}
+
+	summaries := make(map[string]map[string]*model.ReactionSummary)
+	for _, count := range result.Data.([]*model.ReactionCount) {
+		if summaries[count.PostId] == nil {
+			summaries[count.PostId] = make(map[string]*model.ReactionSummary)
+		}
+		summaries[count.PostId][count.EmojiName] = &model.ReactionSummary{Count: count.Count, Me: count.Me}
+	}
+
+	copy := *list
+	copy.Posts = make(map[string]*model.Post, len(list.Posts))
+	for id, post := range list.Posts {
+		summary, ok := summaries[id]
+		if !ok {
+			copy.Posts[id] = post
+			continue
+		}
+
+		metadata := &model.PostMetadata{}
+		if post.Metadata != nil {
+			*metadata = *post.Metadata
+		}
+		metadata.Reactions = summary
+
+		pcopy := *post
+		pcopy.Metadata = metadata
+		copy.Posts[id] = &pcopy
+	}
+
+	return &copy
+}
+
+// getPermalinkPreviews returns previews of the posts with the given ids which haven't been deleted and
+// which userId can read, keyed by post id.
+func (a *App) getPermalinkPreviews(postIds []string, userId string) map[string]*model.PermalinkPreview {
+	previews := make(map[string]*model.PermalinkPreview)
+
+	result := <-a.Srv.Store.Post().GetPostsByIds(postIds)
+	if result.Err != nil {
+		mlog.Warn(fmt.Sprintf("Unable to get linked posts for previews, err=%v", result.Err))
+		return previews
+	}
+
+	canRead := make(map[string]bool)
+	channels := make(map[string]*model.Channel)
+	users := make(map[string]*model.User)
+
+	for _, post := range result.Data.([]*model.Post) {
+		if post.IsSystemMessage() {
+			continue
+		}
+
+		allowed, ok := canRead[post.ChannelId]
+		if !ok {
+			allowed = a.HasPermissionToChannel(userId, post.ChannelId, model.PERMISSION_READ_CHANNEL)
+			canRead[post.ChannelId] = allowed
+		}
+
+		if !allowed {
+			continue
+		}
+
+		channel, ok := channels[post.ChannelId]
+		if !ok {
+			var err *model.AppError
+			if channel, err = a.GetChannel(post.ChannelId); err != nil {
+				continue
+			}
+			channels[post.ChannelId] = channel
+		}
+
+		user, ok := users[post.UserId]
+		if !ok {
+			var err *model.AppError
+			if user, err = a.GetUser(post.UserId); err != nil {
+				continue
+			}
+			users[post.UserId] = user
+		}
+
+		previews[post.Id] = &model.PermalinkPreview{
+			PostId:             post.Id,
+			UserId:             user.Id,
+			Username:           user.Username,
+			ChannelId:          channel.Id,
+			ChannelDisplayName: channel.DisplayName,
+			CreateAt:           post.CreateAt,
+			Message:            model.TruncateRunes(post.Message, model.POST_PERMALINK_PREVIEW_MAX_RUNES),
+			ThumbnailURL:       a.getPermalinkPreviewThumbnailURL(post),
+			UserDeactivated:    user.DeleteAt != 0,
+		}
+	}
+
+	return previews
+}
+
+func (a *App) getPermalinkPreviewThumbnailURL(post *model.Post) string {
+	if len(post.FileIds) == 0 {
+		return ""
+	}
+
+	infos, err := a.GetFileInfosForPost(post.Id, false)
+	if err != nil {
+		return ""
+	}
+
+	for _, info := range infos {
+		if info.IsImage() && info.HasPreviewImage {
+			return a.GetSiteURL() + model.API_URL_SUFFIX + "/files/" + info.Id + "/thumbnail"
+		}
+	}
+
+	return ""
+}
+
+// getFileInfosForPosts returns the file infos attached to each post in list that has any, keyed by post id.
+func (a *App) getFileInfosForPosts(list *model.PostList) map[string][]*model.FileInfo {
+	var postIds []string
+	for id, post := range list.Posts {
+		if len(post.FileIds) > 0 {
+			postIds = append(postIds, id)
+		}
+	}
+
+	if len(postIds) == 0 {
+		return nil
+	}
+
+	result := <-a.Srv.Store.FileInfo().GetForPosts(postIds)
+	if result.Err != nil {
+		mlog.Warn(fmt.Sprintf("Unable to get file infos for posts, err=%v", result.Err))
+		return nil
+	}
+
+	files := make(map[string][]*model.FileInfo)
+	for _, info := range result.Data.([]*model.FileInfo) {
+		files[info.PostId] = append(files[info.PostId], info)
+	}
+
+	return files
+}
+
+// getCustomEmojisForPosts returns the custom emojis used in the message or reactions of each post in list, keyed
+// by post id and sorted by name.
+func (a *App) getCustomEmojisForPosts(list *model.PostList) map[string][]*model.Emoji {
+	if !*a.Config().ServiceSettings.EnableCustomEmoji {
+		return nil
+	}
+
+	namesByPost := make(map[string][]string)
+	var names []string
+	seen := make(map[string]bool)
+
+	for id, post := range list.Posts {
+		postNames := model.FindCustomEmojiNames(post.Message)
+		if post.Metadata != nil {
+			for name := range post.Metadata.Reactions {
+				if _, ok := model.SystemEmojis[name]; !ok {
+					postNames = append(postNames, name)
+				}
+			}
+		}
+
+		namesByPost[id] = postNames
+		for _, name := range postNames {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	result := <-a.Srv.Store.Emoji().GetMultipleByName(names)
+	if result.Err != nil {
+		mlog.Warn(fmt.Sprintf("Unable to get custom emojis for posts, err=%v", result.Err))
+		return nil
+	}
+
+	emojisByName := make(map[string]*model.Emoji)
+	for _, emoji := range result.Data.([]*model.Emoji) {
+		emojisByName[emoji.Name] = emoji
+	}
+
+	emojis := make(map[string][]*model.Emoji)
+	for id, postNames := range namesByPost {
+		added := make(map[string]bool)
+		for _, name := range postNames {
+			if emoji, ok := emojisByName[name]; ok && !added[name] {
+				added[name] = true
+				emojis[id] = append(emojis[id], emoji)
+			}
+		}
+
+		sort.Slice(emojis[id], func(i, j int) bool { return emojis[id][i].Name < emojis[id][j].Name })
+	}
+
+	return emojis
+}
+
+// getLinkEmbedsForPosts returns an embed for the first link in each post in list, keyed by post id. Links to
+// images are embedded as they are. Other links are embedded with their OpenGraph metadata if link previews are
+// enabled, which is fetched for all of the links at once and cached so that it's normally only fetched the first
+// time that a post is loaded.
+func (a *App) getLinkEmbedsForPosts(list *model.PostList) map[string]*model.PostEmbed {
+	siteURL := a.GetSiteURL()
+	enableLinkPreviews := *a.Config().ServiceSettings.EnableLinkPreviews
+
+	embeds := make(map[string]*model.PostEmbed)
+	openGraphLinks := make(map[string][]string)
+
+	for id, post := range list.Posts {
+		if post.IsSystemMessage() {
+			continue
+		}
+
+		link := model.FindPostLink(post.Message, siteURL)
+		if link == nil {
+			continue
+		}
+
+		if link.IsImage {
+			embeds[id] = &model.PostEmbed{Type: model.POST_EMBED_IMAGE, URL: link.URL}
+		} else if enableLinkPreviews {
+			openGraphLinks[link.URL] = append(openGraphLinks[link.URL], id)
+		}
+	}
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+
+	for url, postIds := range openGraphLinks {
+		url, postIds := url, postIds
+
+		wg.Add(1)
+		a.Go(func() {
+			defer wg.Done()
+
+			og := a.GetOpenGraphMetadata(url)
+			if !hasOpenGraphPreview(og) {
+				return
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			for _, id := range postIds {
+				embeds[id] = &model.PostEmbed{Type: model.POST_EMBED_OPENGRAPH, URL: url, Data: og}
+			}
+		})
+	}
+
+	wg.Wait()
+
+	return embeds
+}
+
+// hasOpenGraphPreview returns whether og has enough in it to show a preview, which it won't if the site couldn't
+// be reached or doesn't provide any metadata.
+func hasOpenGraphPreview(og *opengraph.OpenGraph) bool {
+	return og.Title != "" || og.Description != ""
+}
@@ -0,0 +1,423 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/dyatlov/go-opengraph/opengraph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func TestPostListWithPermalinkPreviews(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.SiteURL = "http://localhost:8065" })
+
+	th.AddUserToChannel(th.BasicUser2, th.BasicChannel)
+
+	private := th.createChannel(th.BasicTeam, model.CHANNEL_PRIVATE)
+	privatePost := th.CreatePost(private)
+	publicPost := th.CreatePost(th.BasicChannel)
+
+	permalink := func(post *model.Post) string {
+		return "http://localhost:8065/" + th.BasicTeam.Name + "/pl/" + post.Id
+	}
+
+	linking, err := th.App.CreatePost(&model.Post{
+		ChannelId: th.BasicChannel.Id,
+		UserId:    th.BasicUser.Id,
+		Message:   "see " + permalink(privatePost) + " and " + permalink(publicPost),
+	}, th.BasicChannel, false)
+	require.Nil(t, err)
+
+	t.Run("member of both channels", func(t *testing.T) {
+		post := th.App.PostWithPermalinkPreviews(linking, th.BasicUser.Id)
+		require.NotNil(t, post.Metadata)
+		require.Len(t, post.Metadata.Embeds, 2)
+
+		embed := post.Metadata.Embeds[0]
+		assert.Equal(t, model.POST_EMBED_PERMALINK, embed.Type)
+		assert.Equal(t, permalink(privatePost), embed.URL)
+
+		preview := embed.Data.(*model.PermalinkPreview)
+		assert.Equal(t, privatePost.Id, preview.PostId)
+		assert.Equal(t, th.BasicUser.Id, preview.UserId)
+		assert.Equal(t, th.BasicUser.Username, preview.Username)
+		assert.Equal(t, private.Id, preview.ChannelId)
+		assert.Equal(t, private.DisplayName, preview.ChannelDisplayName)
+		assert.Equal(t, privatePost.CreateAt, preview.CreateAt)
+		assert.Equal(t, privatePost.Message, preview.Message)
+		assert.False(t, preview.UserDeactivated)
+
+		assert.Equal(t, publicPost.Id, post.Metadata.Embeds[1].Data.(*model.PermalinkPreview).PostId)
+
+		// the post isn't changed for anyone else
+		assert.Nil(t, linking.Metadata)
+	})
+
+	t.Run("not a member of the private channel", func(t *testing.T) {
+		post := th.App.PostWithPermalinkPreviews(linking, th.BasicUser2.Id)
+		require.NotNil(t, post.Metadata)
+		require.Len(t, post.Metadata.Embeds, 1)
+		assert.Equal(t, publicPost.Id, post.Metadata.Embeds[0].Data.(*model.PermalinkPreview).PostId)
+	})
+
+	t.Run("checked again when access changes", func(t *testing.T) {
+		th.AddUserToChannel(th.BasicUser2, private)
+
+		post := th.App.PostWithPermalinkPreviews(linking, th.BasicUser2.Id)
+		require.NotNil(t, post.Metadata)
+		assert.Len(t, post.Metadata.Embeds, 2)
+
+		require.Nil(t, th.App.RemoveUserFromChannel(th.BasicUser2.Id, th.BasicUser.Id, private))
+
+		post = th.App.PostWithPermalinkPreviews(linking, th.BasicUser2.Id)
+		require.NotNil(t, post.Metadata)
+		assert.Len(t, post.Metadata.Embeds, 1)
+	})
+
+	t.Run("deactivated author", func(t *testing.T) {
+		author := th.CreateUser()
+		th.LinkUserToTeam(author, th.BasicTeam)
+		th.AddUserToChannel(author, th.BasicChannel)
+
+		authorPost, err := th.App.CreatePost(&model.Post{ChannelId: th.BasicChannel.Id, UserId: author.Id, Message: "message"}, th.BasicChannel, false)
+		require.Nil(t, err)
+
+		_, err = th.App.UpdateActive(author, false)
+		require.Nil(t, err)
+
+		post := th.App.PostWithPermalinkPreviews(&model.Post{Id: model.NewId(), ChannelId: th.BasicChannel.Id, Message: permalink(authorPost)}, th.BasicUser.Id)
+		require.NotNil(t, post.Metadata)
+		require.Len(t, post.Metadata.Embeds, 1)
+
+		preview := post.Metadata.Embeds[0].Data.(*model.PermalinkPreview)
+		assert.Equal(t, author.Username, preview.Username)
+		assert.True(t, preview.UserDeactivated)
+	})
+
+	t.Run("deleted linked post", func(t *testing.T) {
+		_, err := th.App.DeletePost(publicPost.Id)
+		require.Nil(t, err)
+
+		post := th.App.PostWithPermalinkPreviews(linking, th.BasicUser2.Id)
+		assert.Nil(t, post.Metadata)
+		assert.Equal(t, linking.Message, post.Message)
+	})
+
+	t.Run("post lists", func(t *testing.T) {
+		list := model.NewPostList()
+		list.AddPost(linking)
+		list.AddPost(privatePost)
+
+		rlist := th.App.PostListWithPermalinkPreviews(list, th.BasicUser.Id)
+		require.NotNil(t, rlist.Posts[linking.Id].Metadata)
+		assert.Len(t, rlist.Posts[linking.Id].Metadata.Embeds, 1)
+		assert.Nil(t, rlist.Posts[privatePost.Id].Metadata)
+		assert.Equal(t, list.Order, rlist.Order)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnablePermalinkPreviews = false })
+		defer th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnablePermalinkPreviews = true })
+
+		post := th.App.PostWithPermalinkPreviews(linking, th.BasicUser.Id)
+		assert.Nil(t, post.Metadata)
+	})
+}
+
+func TestPostListWithReactionSummaries(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	react := func(post *model.Post, userId string, emojiName string) {
+		_, err := th.App.SaveReactionForPost(&model.Reaction{UserId: userId, PostId: post.Id, EmojiName: emojiName})
+		require.Nil(t, err)
+	}
+
+	getPost := func(post *model.Post) *model.Post {
+		post, err := th.App.GetSinglePost(post.Id)
+		require.Nil(t, err)
+		return post
+	}
+
+	t.Run("me flag", func(t *testing.T) {
+		post := th.CreatePost(th.BasicChannel)
+		react(post, th.BasicUser.Id, "smile")
+		react(post, th.BasicUser2.Id, "smile")
+		react(post, th.BasicUser2.Id, "sad")
+
+		post = getPost(post)
+
+		forUser1 := th.App.PostListWithMetadata(postListOf(post), th.BasicUser.Id).Posts[post.Id]
+		require.NotNil(t, forUser1.Metadata)
+		require.Len(t, forUser1.Metadata.Reactions, 2)
+		assert.Equal(t, &model.ReactionSummary{Count: 2, Me: true}, forUser1.Metadata.Reactions["smile"])
+		assert.Equal(t, &model.ReactionSummary{Count: 1, Me: false}, forUser1.Metadata.Reactions["sad"])
+
+		forUser2 := th.App.PostListWithMetadata(postListOf(post), th.BasicUser2.Id).Posts[post.Id]
+		require.NotNil(t, forUser2.Metadata)
+		assert.Equal(t, &model.ReactionSummary{Count: 2, Me: true}, forUser2.Metadata.Reactions["smile"])
+		assert.Equal(t, &model.ReactionSummary{Count: 1, Me: true}, forUser2.Metadata.Reactions["sad"])
+
+		forOther := th.App.PostWithMetadata(post, model.NewId())
+		assert.False(t, forOther.Metadata.Reactions["smile"].Me)
+		assert.False(t, forOther.Metadata.Reactions["sad"].Me)
+
+		// the post isn't changed for anyone else
+		assert.Nil(t, post.Metadata)
+	})
+
+	t.Run("no reactions", func(t *testing.T) {
+		post := th.CreatePost(th.BasicChannel)
+		assert.Nil(t, th.App.PostWithMetadata(post, th.BasicUser.Id).Metadata)
+	})
+
+	t.Run("payload size", func(t *testing.T) {
+		post := th.CreatePost(th.BasicChannel)
+		for i := 0; i < 200; i++ {
+			emojiName := "smile"
+			if i%2 == 1 {
+				emojiName = "sad"
+			}
+			react(post, model.NewId(), emojiName)
+		}
+
+		post = getPost(post)
+
+		reactions, err := th.App.GetReactionsForPost(post.Id)
+		require.Nil(t, err)
+		require.Len(t, reactions, 200)
+
+		withSummary := th.App.PostWithMetadata(post, th.BasicUser.Id)
+		summarySize := len(withSummary.ToJson()) - len(post.ToJson())
+		fullSize := len(model.ReactionsToJson(reactions))
+
+		assert.True(t, summarySize > 0)
+		assert.True(t, summarySize*50 < fullSize, "summary of %v bytes should be much smaller than the %v bytes of reactions", summarySize, fullSize)
+	})
+}
+
+func postListOf(post *model.Post) *model.PostList {
+	list := model.NewPostList()
+	list.AddPost(post)
+	return list
+}
+
+// postMetadataTestStore serves reactions, file infos and emojis from memory and counts how often each is queried.
+type postMetadataTestStore struct {
+	store.Store
+
+	lock  sync.Mutex
+	calls map[string]int
+
+	reactions []*model.ReactionCount
+	files     []*model.FileInfo
+	emojis    []*model.Emoji
+}
+
+func (s *postMetadataTestStore) count(name string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.calls[name]++
+}
+
+func (s *postMetadataTestStore) Reaction() store.ReactionStore {
+	return &postMetadataTestReactionStore{s: s}
+}
+func (s *postMetadataTestStore) FileInfo() store.FileInfoStore {
+	return &postMetadataTestFileInfoStore{s: s}
+}
+func (s *postMetadataTestStore) Emoji() store.EmojiStore { return &postMetadataTestEmojiStore{s: s} }
+
+type postMetadataTestReactionStore struct {
+	store.ReactionStore
+	s *postMetadataTestStore
+}
+
+func (rs *postMetadataTestReactionStore) GetCountsForPosts(postIds []string, userId string) store.StoreChannel {
+	rs.s.count("GetCountsForPosts")
+
+	var counts []*model.ReactionCount
+	for _, count := range rs.s.reactions {
+		for _, postId := range postIds {
+			if count.PostId == postId {
+				counts = append(counts, count)
+			}
+		}
+	}
+
+	return writeBehindTestResult(counts)
+}
+
+type postMetadataTestFileInfoStore struct {
+	store.FileInfoStore
+	s *postMetadataTestStore
+}
+
+func (fs *postMetadataTestFileInfoStore) GetForPosts(postIds []string) store.StoreChannel {
+	fs.s.count("GetForPosts")
+
+	var infos []*model.FileInfo
+	for _, info := range fs.s.files {
+		for _, postId := range postIds {
+			if info.PostId == postId {
+				infos = append(infos, info)
+			}
+		}
+	}
+
+	return writeBehindTestResult(infos)
+}
+
+type postMetadataTestEmojiStore struct {
+	store.EmojiStore
+	s *postMetadataTestStore
+}
+
+func (es *postMetadataTestEmojiStore) GetMultipleByName(names []string) store.StoreChannel {
+	es.s.count("GetMultipleByName")
+
+	var emojis []*model.Emoji
+	for _, emoji := range es.s.emojis {
+		for _, name := range names {
+			if emoji.Name == name {
+				emojis = append(emojis, emoji)
+			}
+		}
+	}
+
+	return writeBehindTestResult(emojis)
+}
+
+func TestPostListWithExtendedMetadata(t *testing.T) {
+	var pageRequests int
+	var pageLock sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pageLock.Lock()
+		pageRequests++
+		pageLock.Unlock()
+
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><head><meta property="og:title" content="Title of %v"></head></html>`, r.URL.Path)
+	}))
+	defer server.Close()
+
+	userId := model.NewId()
+	partyParrot := &model.Emoji{Id: model.NewId(), Name: "party_parrot"}
+	coolCat := &model.Emoji{Id: model.NewId(), Name: "cool_cat"}
+
+	posts := []*model.Post{
+		{Id: model.NewId(), Message: "plain"},
+		{Id: model.NewId(), Message: "with files", FileIds: model.StringArray{model.NewId(), model.NewId()}},
+		{Id: model.NewId(), Message: "reacted to :party_parrot: :smile:", HasReactions: true},
+		{Id: model.NewId(), Message: "an image https://www.example.com/cat.png"},
+		{Id: model.NewId(), Message: "a website " + server.URL + "/first"},
+		{Id: model.NewId(), Message: "the same website again " + server.URL + "/first"},
+		{Id: model.NewId(), Message: "another website " + server.URL + "/second and " + server.URL + "/third"},
+		{Id: model.NewId(), Message: "no such emoji :not_an_emoji:"},
+	}
+
+	testStore := &postMetadataTestStore{
+		calls: make(map[string]int),
+		reactions: []*model.ReactionCount{
+			{PostId: posts[2].Id, EmojiName: "cool_cat", Count: 2, Me: true},
+			{PostId: posts[2].Id, EmojiName: "smile", Count: 1},
+		},
+		files: []*model.FileInfo{
+			{Id: posts[1].FileIds[0], PostId: posts[1].Id, Name: "a.txt"},
+			{Id: posts[1].FileIds[1], PostId: posts[1].Id, Name: "b.txt"},
+		},
+		emojis: []*model.Emoji{partyParrot, coolCat},
+	}
+
+	cfg := &model.Config{}
+	cfg.SetDefaults()
+	*cfg.ServiceSettings.EnableCustomEmoji = true
+	*cfg.ServiceSettings.EnableLinkPreviews = true
+	*cfg.ServiceSettings.AllowedUntrustedInternalConnections = "127.0.0.1"
+
+	a := &App{Srv: &Server{Store: testStore}}
+	a.config.Store(cfg)
+
+	list := model.NewPostList()
+	for _, post := range posts {
+		list.AddPost(post)
+		list.AddOrder(post.Id)
+	}
+
+	rlist := a.PostListWithExtendedMetadata(list, userId)
+
+	t.Run("batched", func(t *testing.T) {
+		assert.Equal(t, map[string]int{"GetCountsForPosts": 1, "GetForPosts": 1, "GetMultipleByName": 1}, testStore.calls)
+		assert.Equal(t, 2, pageRequests, "should only have fetched each website once")
+	})
+
+	t.Run("payload", func(t *testing.T) {
+		assert.Equal(t, list.Order, rlist.Order)
+
+		assert.Nil(t, rlist.Posts[posts[0].Id].Metadata)
+
+		files := rlist.Posts[posts[1].Id].Metadata
+		require.NotNil(t, files)
+		assert.Equal(t, testStore.files, files.Files)
+		assert.Empty(t, files.Embeds)
+
+		reacted := rlist.Posts[posts[2].Id].Metadata
+		require.NotNil(t, reacted)
+		assert.Equal(t, map[string]*model.ReactionSummary{
+			"cool_cat": {Count: 2, Me: true},
+			"smile":    {Count: 1},
+		}, reacted.Reactions)
+		assert.Equal(t, []*model.Emoji{coolCat, partyParrot}, reacted.Emojis)
+
+		image := rlist.Posts[posts[3].Id].Metadata
+		require.NotNil(t, image)
+		assert.Equal(t, []*model.PostEmbed{{Type: model.POST_EMBED_IMAGE, URL: "https://www.example.com/cat.png"}}, image.Embeds)
+
+		for i, path := range map[int]string{4: "/first", 5: "/first", 6: "/second"} {
+			metadata := rlist.Posts[posts[i].Id].Metadata
+			require.NotNil(t, metadata)
+			require.Len(t, metadata.Embeds, 1)
+			assert.Equal(t, model.POST_EMBED_OPENGRAPH, metadata.Embeds[0].Type)
+			assert.Equal(t, server.URL+path, metadata.Embeds[0].URL)
+			assert.Equal(t, "Title of "+path, metadata.Embeds[0].Data.(*opengraph.OpenGraph).Title)
+		}
+
+		assert.Nil(t, rlist.Posts[posts[7].Id].Metadata)
+
+		// the original posts aren't changed
+		for _, post := range posts {
+			assert.Nil(t, post.Metadata)
+		}
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		a.PostListWithExtendedMetadata(list, userId)
+		assert.Equal(t, 2, pageRequests, "should have used the cached OpenGraph metadata")
+	})
+
+	t.Run("without link previews or custom emoji", func(t *testing.T) {
+		cfg := a.Config().Clone()
+		*cfg.ServiceSettings.EnableCustomEmoji = false
+		*cfg.ServiceSettings.EnableLinkPreviews = false
+		a.config.Store(cfg)
+
+		rlist := a.PostListWithExtendedMetadata(list, userId)
+		assert.Nil(t, rlist.Posts[posts[2].Id].Metadata.Emojis)
+		assert.Nil(t, rlist.Posts[posts[4].Id].Metadata)
+		assert.Len(t, rlist.Posts[posts[3].Id].Metadata.Embeds, 1, "should still embed images")
+	})
+}
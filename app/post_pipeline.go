@@ -0,0 +1,151 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"hash/fnv"
+	"runtime"
+	"sync"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// POST_PIPELINE_OVERLOAD_THRESHOLD is how full, as a fraction of its capacity, a worker's queue can get before the
+// jobs added to it are told to shed their optional work.
+const POST_PIPELINE_OVERLOAD_THRESHOLD = 0.75
+
+// postPipeline runs the work that follows saving a post, such as sending email and push notifications and
+// triggering outgoing webhooks, so that it doesn't add to the time taken to create the post. All of a channel's jobs
+// are run by the same worker so that they happen in the order that they were queued.
+type postPipeline struct {
+	lock    sync.RWMutex
+	queues  []chan *postPipelineJob
+	stopped bool
+	done    sync.WaitGroup
+}
+
+type postPipelineJob struct {
+	postId string
+
+	// overloaded is set if the worker's queue was backed up when the job was added to it
+	overloaded bool
+
+	run func(overloaded bool) *model.AppError
+}
+
+func newPostPipeline(workers int, queueSize int) *postPipeline {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	workerQueueSize := queueSize / workers
+	if workerQueueSize < 1 {
+		workerQueueSize = 1
+	}
+
+	p := &postPipeline{
+		queues: make([]chan *postPipelineJob, workers),
+	}
+
+	for i := range p.queues {
+		p.queues[i] = make(chan *postPipelineJob, workerQueueSize)
+
+		p.done.Add(1)
+		go p.work(p.queues[i])
+	}
+
+	return p
+}
+
+func (p *postPipeline) work(queue chan *postPipelineJob) {
+	defer p.done.Done()
+
+	for job := range queue {
+		job.process()
+	}
+}
+
+func (job *postPipelineJob) process() {
+	if err := job.run(job.overloaded); err != nil {
+		mlog.Error(fmt.Sprintf("Failed to process post after saving it, post_id=%v err=%v", job.postId, err.Error()), mlog.String("post_id", job.postId))
+	}
+}
+
+// queue adds the job to the channel's worker, waiting for space if that worker's queue is full. The job is run
+// immediately if the pipeline has been stopped.
+func (p *postPipeline) queue(channelId string, job *postPipelineJob) {
+	p.lock.RLock()
+
+	if p.stopped {
+		p.lock.RUnlock()
+		job.process()
+		return
+	}
+
+	queue := p.queues[p.queueIndex(channelId)]
+	if float64(len(queue)) >= POST_PIPELINE_OVERLOAD_THRESHOLD*float64(cap(queue)) {
+		job.overloaded = true
+	}
+
+	queue <- job
+
+	p.lock.RUnlock()
+}
+
+func (p *postPipeline) queueIndex(channelId string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(channelId))
+	return int(hash.Sum32() % uint32(len(p.queues)))
+}
+
+// stop waits for every queued job to finish. Any jobs queued afterwards run immediately.
+func (p *postPipeline) stop() {
+	p.lock.Lock()
+	if p.stopped {
+		p.lock.Unlock()
+		return
+	}
+
+	p.stopped = true
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.lock.Unlock()
+
+	p.done.Wait()
+}
+
+func (a *App) startPostPipeline() {
+	workers := *a.Config().ServiceSettings.PostPipelineWorkers
+	if workers == 0 {
+		workers = runtime.NumCPU()
+	}
+
+	a.postPipeline = newPostPipeline(workers, *a.Config().ServiceSettings.PostPipelineQueueSize)
+}
+
+func (a *App) stopPostPipeline() {
+	if a.postPipeline != nil {
+		a.postPipeline.stop()
+	}
+}
+
+// queuePostJob runs f after the jobs already queued for the channel. f is told whether the pipeline is overloaded so
+// that it can skip work that isn't needed, such as batching notification emails. f runs immediately if the pipeline
+// isn't running.
+func (a *App) queuePostJob(post *model.Post, f func(overloaded bool) *model.AppError) {
+	job := &postPipelineJob{
+		postId: post.Id,
+		run:    f,
+	}
+
+	if a.postPipeline == nil {
+		job.process()
+		return
+	}
+
+	a.postPipeline.queue(post.ChannelId, job)
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestPostPipelineKeepsChannelOrder(t *testing.T) {
+	p := newPostPipeline(4, 100)
+
+	channelIds := []string{model.NewId(), model.NewId(), model.NewId(), model.NewId(), model.NewId()}
+
+	var lock sync.Mutex
+	processed := map[string][]int{}
+
+	const jobsPerChannel = 200
+	for i := 0; i < jobsPerChannel; i++ {
+		for _, channelId := range channelIds {
+			i, channelId := i, channelId
+			p.queue(channelId, &postPipelineJob{
+				postId: model.NewId(),
+				run: func(overloaded bool) *model.AppError {
+					lock.Lock()
+					processed[channelId] = append(processed[channelId], i)
+					lock.Unlock()
+					return nil
+				},
+			})
+		}
+	}
+
+	p.stop()
+
+	for _, channelId := range channelIds {
+		require.Len(t, processed[channelId], jobsPerChannel, "every job should've run exactly once")
+		for i, sequence := range processed[channelId] {
+			assert.Equal(t, i, sequence, "jobs should've run in the order that they were queued")
+		}
+	}
+}
+
+func TestPostPipelineOverload(t *testing.T) {
+	p := newPostPipeline(1, 4)
+
+	started := make(chan bool)
+	release := make(chan bool)
+	p.queue(model.NewId(), &postPipelineJob{
+		run: func(overloaded bool) *model.AppError {
+			started <- true
+			<-release
+			return nil
+		},
+	})
+	<-started
+
+	overloaded := make([]bool, 4)
+	for i := range overloaded {
+		i := i
+		p.queue(model.NewId(), &postPipelineJob{
+			run: func(isOverloaded bool) *model.AppError {
+				overloaded[i] = isOverloaded
+				return nil
+			},
+		})
+	}
+
+	close(release)
+	p.stop()
+
+	assert.Equal(t, []bool{false, false, false, true}, overloaded)
+}
+
+func TestPostPipelineStop(t *testing.T) {
+	p := newPostPipeline(2, 10)
+
+	var count int32
+	for i := 0; i < 10; i++ {
+		p.queue(model.NewId(), &postPipelineJob{
+			run: func(overloaded bool) *model.AppError {
+				time.Sleep(time.Millisecond)
+				atomic.AddInt32(&count, 1)
+				return nil
+			},
+		})
+	}
+
+	p.stop()
+	assert.Equal(t, int32(10), atomic.LoadInt32(&count), "should've waited for queued jobs to finish")
+
+	ran := false
+	p.queue(model.NewId(), &postPipelineJob{
+		run: func(overloaded bool) *model.AppError {
+			ran = true
+			return nil
+		},
+	})
+	assert.True(t, ran, "should've run the job immediately once stopped")
+
+	// Stopping again does nothing
+	p.stop()
+}
+
+func TestCreatePostReturnsBeforeWebhooks(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		cfg.ServiceSettings.EnableOutgoingWebhooks = true
+		*cfg.ServiceSettings.AllowedUntrustedInternalConnections = "localhost 127.0.0.1"
+	})
+
+	requests := make(chan string, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		requests <- r.Form.Get("post_id")
+	}))
+	defer ts.Close()
+
+	hook, err := th.App.CreateOutgoingWebhook(&model.OutgoingWebhook{
+		ChannelId:    th.BasicChannel.Id,
+		TeamId:       th.BasicTeam.Id,
+		CreatorId:    th.BasicUser.Id,
+		CallbackURLs: []string{ts.URL},
+	})
+	require.Nil(t, err)
+	defer th.App.DeleteOutgoingWebhook(hook.Id)
+
+	// Hold up the channel's worker so that nothing queued after this runs until it's released
+	release := make(chan bool)
+	th.App.queuePostJob(&model.Post{ChannelId: th.BasicChannel.Id}, func(overloaded bool) *model.AppError {
+		<-release
+		return nil
+	})
+
+	post, err := th.App.CreatePost(&model.Post{
+		UserId:    th.BasicUser.Id,
+		ChannelId: th.BasicChannel.Id,
+		Message:   "message",
+	}, th.BasicChannel, true)
+	require.Nil(t, err)
+
+	select {
+	case <-requests:
+		require.Fail(t, "the webhook shouldn't have been triggered yet")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+
+	select {
+	case postId := <-requests:
+		assert.Equal(t, post.Id, postId)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timed out waiting for the webhook")
+	}
+}
+
+func TestCreatePostSendsPushNotificationsOnce(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	var pushCount int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&pushCount, 1)
+		w.Write([]byte(model.MapToJson(map[string]string{model.PUSH_STATUS: model.PUSH_STATUS_OK})))
+	}))
+	defer ts.Close()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.EmailSettings.SendPushNotifications = true
+		*cfg.EmailSettings.PushNotificationServer = ts.URL
+	})
+
+	th.AddUserToChannel(th.BasicUser2, th.BasicChannel)
+
+	_, err := th.App.CreateSession(&model.Session{
+		UserId:   th.BasicUser2.Id,
+		DeviceId: "android:" + model.NewId(),
+	})
+	require.Nil(t, err)
+
+	_, err = th.App.CreatePost(&model.Post{
+		UserId:    th.BasicUser.Id,
+		ChannelId: th.BasicChannel.Id,
+		Message:   "@" + th.BasicUser2.Username,
+	}, th.BasicChannel, false)
+	require.Nil(t, err)
+
+	// Stopping the pipeline waits for the notification to be sent
+	th.App.stopPostPipeline()
+	th.App.WaitForGoroutines()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&pushCount))
+}
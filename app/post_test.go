@@ -4,8 +4,8 @@
 package app
 
 import (
-	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -48,6 +48,52 @@ func TestUpdatePostEditAt(t *testing.T) {
 	}
 }
 
+func TestUpdatePostHistory(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	post := th.CreatePost(th.BasicChannel)
+	original := post.Message
+
+	_, err := th.App.PatchPost(post.Id, &model.PostPatch{IsPinned: model.NewBool(true)})
+	require.Nil(t, err)
+
+	history, err := th.App.GetPostHistory(post.Id)
+	require.Nil(t, err)
+	assert.Len(t, history, 0, "pinning a post shouldn't be recorded as an edit")
+
+	_, err = th.App.PatchPostByEditor(post.Id, &model.PostPatch{Message: model.NewString("second")}, th.BasicUser2.Id)
+	require.Nil(t, err)
+
+	_, err = th.App.PatchPost(post.Id, &model.PostPatch{Message: model.NewString("third")})
+	require.Nil(t, err)
+
+	history, err = th.App.GetPostHistory(post.Id)
+	require.Nil(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, original, history[0].Message)
+	assert.Equal(t, th.BasicUser2.Id, history[0].EditorId)
+	assert.Equal(t, "second", history[1].Message)
+	assert.Equal(t, post.UserId, history[1].EditorId, "edits made without an editor should be credited to the author")
+
+	_, err = th.App.DeletePost(post.Id)
+	require.Nil(t, err)
+
+	_, err = th.App.GetPostHistory(post.Id)
+	assert.NotNil(t, err, "shouldn't return the history of deleted posts")
+
+	channel := th.CreateChannel(th.BasicTeam)
+	post = th.CreatePost(channel)
+	_, err = th.App.PatchPost(post.Id, &model.PostPatch{Message: model.NewString("edited")})
+	require.Nil(t, err)
+
+	require.Nil(t, th.App.PermanentDeleteChannel(channel))
+
+	result := <-th.App.Srv.Store.Post().GetHistory(post.Id)
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]*model.PostHistory), 0)
+}
+
 func TestUpdatePostTimeLimit(t *testing.T) {
 	th := Setup().InitBasic()
 	defer th.TearDown()
@@ -85,6 +131,110 @@ func TestUpdatePostTimeLimit(t *testing.T) {
 	})
 }
 
+func TestPostTimeLimitBoundaries(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.SetLicense(model.NewTestLicense())
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.ServiceSettings.PostEditTimeLimit = 10
+		*cfg.ServiceSettings.PostDeleteTimeLimit = 0
+	})
+	defer th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.ServiceSettings.PostEditTimeLimit = -1
+		*cfg.ServiceSettings.PostDeleteTimeLimit = -1
+	})
+
+	post := &model.Post{ChannelId: th.BasicChannel.Id, UserId: th.BasicUser.Id}
+
+	t.Run("inside the window", func(t *testing.T) {
+		post.CreateAt = model.GetMillis() - 9000
+		assert.Nil(t, th.App.CheckPostEditTimeLimit(th.BasicUser.Id, post))
+	})
+
+	t.Run("after the window", func(t *testing.T) {
+		post.CreateAt = model.GetMillis() - 10500
+		err := th.App.CheckPostEditTimeLimit(th.BasicUser.Id, post)
+		if assert.NotNil(t, err) {
+			assert.Equal(t, "api.post.update_post.permissions_time_limit.app_error", err.Id)
+			assert.Contains(t, err.DetailedError, "window_ended=")
+		}
+	})
+
+	t.Run("never", func(t *testing.T) {
+		post.CreateAt = model.GetMillis()
+		err := th.App.CheckPostDeleteTimeLimit(th.BasicUser.Id, post)
+		if assert.NotNil(t, err) {
+			assert.Equal(t, "api.post.delete_post.permissions_never.app_error", err.Id)
+		}
+	})
+
+	t.Run("without a license", func(t *testing.T) {
+		th.App.SetLicense(nil)
+		defer th.App.SetLicense(model.NewTestLicense())
+
+		post.CreateAt = model.GetMillis() - 10500
+		assert.Nil(t, th.App.CheckPostEditTimeLimit(th.BasicUser.Id, post))
+		assert.Nil(t, th.App.CheckPostDeleteTimeLimit(th.BasicUser.Id, post))
+	})
+}
+
+func TestPostTimeLimitRoleOverrides(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.SetLicense(model.NewTestLicense())
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.ServiceSettings.PostEditTimeLimit = 300
+		cfg.ServiceSettings.PostEditTimeLimitByRole = map[string]int{model.CHANNEL_ADMIN_ROLE_ID: -1}
+		*cfg.ServiceSettings.PostDeleteTimeLimit = 300
+		cfg.ServiceSettings.PostDeleteTimeLimitByRole = map[string]int{model.TEAM_ADMIN_ROLE_ID: 3600}
+	})
+	defer th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.ServiceSettings.PostEditTimeLimit = -1
+		cfg.ServiceSettings.PostEditTimeLimitByRole = map[string]int{}
+		*cfg.ServiceSettings.PostDeleteTimeLimit = -1
+		cfg.ServiceSettings.PostDeleteTimeLimitByRole = map[string]int{}
+	})
+
+	post, err := th.App.CreatePost(&model.Post{
+		ChannelId: th.BasicChannel.Id,
+		UserId:    th.BasicUser.Id,
+		Message:   "message",
+		CreateAt:  model.GetMillis() - 600*1000,
+	}, th.BasicChannel, false)
+	require.Nil(t, err)
+
+	err = th.App.CheckPostEditTimeLimit(th.BasicUser.Id, post)
+	require.NotNil(t, err)
+	require.NotNil(t, th.App.CheckPostDeleteTimeLimit(th.BasicUser.Id, post))
+
+	edit := &model.Post{Id: post.Id, Message: "edited"}
+	_, err = th.App.UpdatePost(edit, true)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "api.post.update_post.permissions_time_limit.app_error", err.Id)
+	}
+
+	_, err = th.App.UpdateChannelMemberRoles(th.BasicChannel.Id, th.BasicUser.Id, "channel_user channel_admin")
+	require.Nil(t, err)
+
+	assert.Nil(t, th.App.CheckPostEditTimeLimit(th.BasicUser.Id, post))
+	assert.NotNil(t, th.App.CheckPostDeleteTimeLimit(th.BasicUser.Id, post))
+
+	_, err = th.App.UpdatePostByEditor(edit, true, th.BasicUser.Id)
+	assert.Nil(t, err)
+
+	// another user on the post's team doesn't get the override
+	assert.NotNil(t, th.App.CheckPostEditTimeLimit(th.BasicUser2.Id, post))
+
+	_, err = th.App.UpdateTeamMemberRoles(th.BasicTeam.Id, th.BasicUser2.Id, "team_user team_admin")
+	require.Nil(t, err)
+
+	assert.Nil(t, th.App.CheckPostDeleteTimeLimit(th.BasicUser2.Id, post))
+}
+
 func TestPostReplyToPostWhereRootPosterLeftChannel(t *testing.T) {
 	// This test ensures that when replying to a root post made by a user who has since left the channel, the reply
 	// post completes successfully. This is a regression test for PLT-6523.
@@ -127,14 +277,30 @@ func TestPostAction(t *testing.T) {
 		*cfg.ServiceSettings.AllowedUntrustedInternalConnections = "localhost 127.0.0.1"
 	})
 
+	response := `{"update": {"message": "updated"}, "ephemeral_text": "foo"}`
+	var lastRequest *model.PostActionIntegrationRequest
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var request model.PostActionIntegrationRequest
-		err := json.NewDecoder(r.Body).Decode(&request)
+		body, err := ioutil.ReadAll(r.Body)
 		assert.NoError(t, err)
+
+		signature := r.Header.Get(model.HEADER_POST_ACTION_SIGNATURE)
+		_, appErr := model.VerifyPostActionIntegrationRequest(body, signature, *th.App.Config().ServiceSettings.PostActionSigningSecret, model.GetMillis())
+		assert.NotNil(t, appErr, "the request should be signed with the integration's own secret")
+
+		secret, appErr := th.App.GetPostActionSigningSecret("http://" + r.Host)
+		assert.Nil(t, appErr)
+		request, appErr := model.VerifyPostActionIntegrationRequest(body, signature, secret, model.GetMillis())
+		if !assert.Nil(t, appErr, "the request should be signed") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
 		assert.Equal(t, request.UserId, th.BasicUser.Id)
+		assert.Equal(t, th.BasicChannel.Id, request.ChannelId)
+		assert.Equal(t, th.BasicTeam.Id, request.TeamId)
 		assert.Equal(t, "foo", request.Context["s"])
 		assert.EqualValues(t, 3, request.Context["n"])
-		fmt.Fprintf(w, `{"update": {"message": "updated"}, "ephemeral_text": "foo"}`)
+		lastRequest = request
+		fmt.Fprint(w, response)
 	}))
 	defer ts.Close()
 
@@ -179,6 +345,71 @@ func TestPostAction(t *testing.T) {
 
 	err = th.App.DoPostAction(post.Id, attachments[0].Actions[0].Id, th.BasicUser.Id)
 	require.Nil(t, err)
+	assert.Equal(t, post.Id, lastRequest.PostId)
+	assert.Equal(t, attachments[0].Actions[0].Id, lastRequest.ActionId)
+	firstRequestId := lastRequest.RequestId
+
+	updated, err := th.App.GetSinglePost(post.Id)
+	require.Nil(t, err)
+	assert.Equal(t, "updated", updated.Message)
+
+	t.Run("disable actions", func(t *testing.T) {
+		interactivePost.Id = ""
+		interactivePost.PendingPostId = model.NewId() + ":" + fmt.Sprint(model.GetMillis())
+		interactivePost.Props["attachments"] = []*model.SlackAttachment{
+			{
+				Text: "hello",
+				Actions: []*model.PostAction{
+					{
+						Id:          "deploy",
+						Name:        "Deploy",
+						Integration: &model.PostActionIntegration{URL: ts.URL, Context: model.StringInterface{"s": "foo", "n": 3}},
+						Confirm:     &model.PostActionConfirm{Title: "Deploy to production?", Message: "This can't be undone."},
+					},
+					{
+						Id:          "cancel",
+						Name:        "Cancel",
+						Integration: &model.PostActionIntegration{URL: ts.URL, Context: model.StringInterface{"s": "foo", "n": 3}},
+					},
+				},
+			},
+		}
+		post, err := th.App.CreatePostAsUser(&interactivePost)
+		require.Nil(t, err)
+
+		response = `{"props": {"status": "deployed"}, "disable_actions": ["deploy"]}`
+		err = th.App.DoPostAction(post.Id, "deploy", th.BasicUser.Id)
+		require.Nil(t, err)
+		assert.NotEqual(t, firstRequestId, lastRequest.RequestId, "each request should have its own id")
+
+		updated, err := th.App.GetSinglePost(post.Id)
+		require.Nil(t, err)
+		assert.Equal(t, "Interactive post", updated.Message)
+		assert.Equal(t, int64(0), updated.EditAt, "changing only the actions shouldn't mark the post as edited")
+		assert.Equal(t, "deployed", updated.Props["status"])
+		assert.True(t, updated.GetAction("deploy").Disabled)
+		assert.Equal(t, "Deploy to production?", updated.GetAction("deploy").Confirm.Title)
+		assert.False(t, updated.GetAction("cancel").Disabled)
+		assert.NotNil(t, updated.GetAction("cancel").Integration, "the remaining actions should still work")
+
+		err = th.App.DoPostAction(post.Id, "deploy", th.BasicUser.Id)
+		require.NotNil(t, err, "disabled actions can't be performed")
+		assert.Equal(t, "api.post.do_action.disabled.app_error", err.Id)
+
+		response = `{"props": {"status": null, "attachments": [{"text": "deploy cancelled", "actions": [{"name": "Retry", "integration": {"url": "` + ts.URL + `"}}]}]}}`
+		err = th.App.DoPostAction(post.Id, "cancel", th.BasicUser.Id)
+		require.Nil(t, err)
+
+		updated, err = th.App.GetSinglePost(post.Id)
+		require.Nil(t, err)
+		assert.Nil(t, updated.Props["status"])
+		assert.Nil(t, updated.GetAction("deploy"), "the actions should be replaced")
+		updatedAttachments := updated.Attachments()
+		require.Len(t, updatedAttachments, 1)
+		require.Len(t, updatedAttachments[0].Actions, 1)
+		assert.Equal(t, "Retry", updatedAttachments[0].Actions[0].Name)
+		assert.NotEmpty(t, updatedAttachments[0].Actions[0].Id, "new actions should be given ids")
+	})
 }
 
 func TestPostChannelMentions(t *testing.T) {
@@ -470,3 +701,282 @@ func TestMaxPostSize(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckChannelMentionsLimit(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.AddUserToChannel(th.BasicUser2, th.BasicChannel)
+
+	memberCount := th.App.Srv.Store.Channel().GetMemberCountFromCache(th.BasicChannel.Id)
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.TeamSettings.MaxNotificationsPerChannel = memberCount - 1 })
+
+	post := &model.Post{UserId: th.BasicUser.Id, ChannelId: th.BasicChannel.Id, Message: "@channel announcement"}
+
+	t.Run("too many members", func(t *testing.T) {
+		err := th.App.CheckChannelMentionsLimit(post)
+		require.NotNil(t, err)
+		assert.Equal(t, "api.post.check_channel_mentions_limit.app_error", err.Id)
+		assert.Equal(t, http.StatusPreconditionRequired, err.StatusCode)
+
+		for _, message := range []string{"@all of you", "hey @here."} {
+			assert.NotNil(t, th.App.CheckChannelMentionsLimit(&model.Post{UserId: th.BasicUser.Id, ChannelId: th.BasicChannel.Id, Message: message}))
+		}
+	})
+
+	t.Run("no channel wide mentions", func(t *testing.T) {
+		assert.Nil(t, th.App.CheckChannelMentionsLimit(&model.Post{UserId: th.BasicUser.Id, ChannelId: th.BasicChannel.Id, Message: "@" + th.BasicUser2.Username}))
+		assert.Nil(t, th.App.CheckChannelMentionsLimit(&model.Post{UserId: th.BasicUser.Id, ChannelId: th.BasicChannel.Id, Message: "`@channel`"}))
+	})
+
+	t.Run("few enough members", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.TeamSettings.MaxNotificationsPerChannel = memberCount })
+		defer th.App.UpdateConfig(func(cfg *model.Config) { *cfg.TeamSettings.MaxNotificationsPerChannel = memberCount - 1 })
+
+		assert.Nil(t, th.App.CheckChannelMentionsLimit(post))
+	})
+
+	t.Run("channel mentions disabled", func(t *testing.T) {
+		disabled := true
+		channel, err := th.App.PatchChannel(th.CreateChannel(th.BasicTeam), &model.ChannelPatch{DisableChannelMentions: &disabled}, th.BasicUser.Id)
+		require.Nil(t, err)
+
+		assert.Nil(t, th.App.CheckChannelMentionsLimit(&model.Post{UserId: th.BasicUser.Id, ChannelId: channel.Id, Message: "@channel announcement"}))
+	})
+}
+
+func TestDisableChannelMentions(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.AddUserToChannel(th.BasicUser2, th.BasicChannel)
+
+	post := &model.Post{UserId: th.BasicUser.Id, ChannelId: th.BasicChannel.Id, Message: "@channel announcement", CreateAt: model.GetMillis()}
+
+	mentions, err := th.App.SendNotifications(post, th.BasicTeam, th.BasicChannel, th.BasicUser, nil)
+	require.Nil(t, err)
+	assert.Contains(t, mentions, th.BasicUser2.Id)
+
+	disabled := true
+	channel, err := th.App.PatchChannel(th.BasicChannel, &model.ChannelPatch{DisableChannelMentions: &disabled}, th.BasicUser.Id)
+	require.Nil(t, err)
+	require.True(t, channel.DisableChannelMentions)
+
+	// not even the channel admin can notify everyone
+	mentions, err = th.App.SendNotifications(post, th.BasicTeam, channel, th.BasicUser, nil)
+	require.Nil(t, err)
+	assert.NotContains(t, mentions, th.BasicUser2.Id)
+
+	// while other mentions still work
+	post.Message = "@channel @" + th.BasicUser2.Username
+	mentions, err = th.App.SendNotifications(post, th.BasicTeam, channel, th.BasicUser, nil)
+	require.Nil(t, err)
+	assert.Contains(t, mentions, th.BasicUser2.Id)
+}
+
+func TestCheckPostProps(t *testing.T) {
+	cfg := &model.Config{}
+	cfg.SetDefaults()
+	*cfg.ServiceSettings.MaxPostPropsSize = 100
+
+	a := &App{}
+	a.config.Store(cfg)
+
+	t.Run("too large", func(t *testing.T) {
+		err := a.checkPostProps(&model.Post{Props: model.StringInterface{"a": strings.Repeat("a", 100)}})
+		require.NotNil(t, err)
+		assert.Equal(t, "app.post.check_props.too_large.app_error", err.Id)
+		assert.Equal(t, http.StatusBadRequest, err.StatusCode)
+
+		assert.Nil(t, a.checkPostProps(&model.Post{Props: model.StringInterface{"a": strings.Repeat("a", 100-len(`{"a":""}`))}}))
+	})
+
+	t.Run("invalid attachments", func(t *testing.T) {
+		err := a.checkPostProps(&model.Post{Props: model.StringInterface{"attachments": []interface{}{map[string]interface{}{"text": 5}}}})
+		require.NotNil(t, err)
+		assert.Equal(t, "model.slack_attachment.is_valid.field_type.app_error", err.Id)
+
+		assert.Nil(t, a.checkPostProps(&model.Post{Props: model.StringInterface{"attachments": []interface{}{map[string]interface{}{"text": "hi"}}}}))
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		post := &model.Post{Props: model.StringInterface{"override_username": "someone", "override_icon_url": "http://example.com/icon"}}
+		assert.Nil(t, a.checkPostProps(post))
+		assert.Equal(t, model.StringInterface{}, post.Props, "overrides should be removed while they're disabled")
+
+		cfg.ServiceSettings.EnablePostUsernameOverride = true
+		defer func() { cfg.ServiceSettings.EnablePostUsernameOverride = false }()
+
+		post = &model.Post{Props: model.StringInterface{"override_username": "someone", "override_icon_url": "http://example.com/icon"}}
+		assert.Nil(t, a.checkPostProps(post))
+		assert.Equal(t, model.StringInterface{"override_username": "someone"}, post.Props)
+
+		err := a.checkPostProps(&model.Post{Props: model.StringInterface{"override_username": 5}})
+		require.NotNil(t, err)
+		assert.Equal(t, "app.post.check_props.override.app_error", err.Id)
+	})
+}
+
+func TestCreatePostPropsLimits(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.MaxPostPropsSize = 1000 })
+
+	t.Run("too large", func(t *testing.T) {
+		_, err := th.App.CreatePost(&model.Post{
+			UserId:    th.BasicUser.Id,
+			ChannelId: th.BasicChannel.Id,
+			Message:   "too large",
+			Props:     model.StringInterface{"data": strings.Repeat("a", 1000)},
+		}, th.BasicChannel, false)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.post.check_props.too_large.app_error", err.Id)
+	})
+
+	t.Run("too large after an edit", func(t *testing.T) {
+		post := th.CreatePost(th.BasicChannel)
+		post.Props = model.StringInterface{"data": strings.Repeat("a", 1000)}
+
+		_, err := th.App.UpdatePost(post, false)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.post.check_props.too_large.app_error", err.Id)
+	})
+
+	t.Run("invalid attachments", func(t *testing.T) {
+		_, err := th.App.CreatePost(&model.Post{
+			UserId:    th.BasicUser.Id,
+			ChannelId: th.BasicChannel.Id,
+			Message:   "invalid attachments",
+			Props:     model.StringInterface{"attachments": "not a list"},
+		}, th.BasicChannel, false)
+		require.NotNil(t, err)
+		assert.Equal(t, "model.slack_attachment.is_valid.not_array.app_error", err.Id)
+	})
+
+	t.Run("reserved props", func(t *testing.T) {
+		post, err := th.App.CreatePost(&model.Post{
+			UserId:    th.BasicUser.Id,
+			ChannelId: th.BasicChannel.Id,
+			Message:   "reserved props",
+			Props:     model.StringInterface{model.POST_PROPS_RESERVED_PREFIX + "unknown": true, "custom": true},
+		}, th.BasicChannel, false)
+		require.Nil(t, err)
+
+		post, err = th.App.GetSinglePost(post.Id)
+		require.Nil(t, err)
+		assert.NotContains(t, post.Props, model.POST_PROPS_RESERVED_PREFIX+"unknown")
+		assert.Equal(t, true, post.Props["custom"])
+	})
+}
+
+func TestCreatePostContentAndFiles(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.FileSettings.MaxFileAttachments = 2
+		cfg.ServiceSettings.EnableIncomingWebhooks = true
+	})
+
+	saveFile := func(creatorId, channelId string) *model.FileInfo {
+		info := &model.FileInfo{CreatorId: creatorId, ChannelId: channelId, Name: "file.txt", Path: "data/" + model.NewId() + "/file.txt"}
+		result := <-th.App.Srv.Store.FileInfo().Save(info)
+		require.Nil(t, result.Err)
+		return info
+	}
+
+	newPost := func(message string, fileIds ...string) *model.Post {
+		return &model.Post{UserId: th.BasicUser.Id, ChannelId: th.BasicChannel.Id, Message: message, FileIds: fileIds}
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		_, err := th.App.CreatePostAsUser(newPost(" \n "))
+		require.NotNil(t, err)
+		assert.Equal(t, "api.post.create_post.empty.app_error", err.Id)
+
+		post := newPost("")
+		post.Type = model.POST_SLACK_ATTACHMENT
+		post.Props = model.StringInterface{"attachments": []*model.SlackAttachment{}}
+		_, err = th.App.CreatePostAsUser(post)
+		require.NotNil(t, err)
+		assert.Equal(t, "api.post.create_post.empty.app_error", err.Id)
+
+		post.Props = model.StringInterface{"attachments": []*model.SlackAttachment{{Text: "attachment"}}}
+		_, err = th.App.CreatePostAsUser(post)
+		assert.Nil(t, err, "a post with only message attachments should be created")
+
+		_, err = th.App.CreatePostAsUser(newPost("", saveFile(th.BasicUser.Id, th.BasicChannel.Id).Id))
+		assert.Nil(t, err, "a post with only files should be created")
+	})
+
+	t.Run("empty from a plugin", func(t *testing.T) {
+		api := &PluginAPI{id: "plugin", app: th.App}
+
+		_, err := api.CreatePost(newPost(""))
+		require.NotNil(t, err)
+		assert.Equal(t, "api.post.create_post.empty.app_error", err.Id)
+	})
+
+	t.Run("empty from a webhook", func(t *testing.T) {
+		hook, err := th.App.CreateIncomingWebhookForChannel(th.BasicUser.Id, th.BasicChannel, &model.IncomingWebhook{ChannelId: th.BasicChannel.Id})
+		require.Nil(t, err)
+		defer th.App.DeleteIncomingWebhook(hook.Id)
+
+		_, err = th.App.HandleIncomingWebhook(hook.Id, &model.IncomingWebhookRequest{Text: " ", Attachments: []*model.SlackAttachment{}})
+		require.NotNil(t, err)
+		assert.Equal(t, "web.incoming_webhook.text.app_error", err.Id)
+	})
+
+	t.Run("too many files", func(t *testing.T) {
+		first := saveFile(th.BasicUser.Id, th.BasicChannel.Id)
+		second := saveFile(th.BasicUser.Id, th.BasicChannel.Id)
+		third := saveFile(th.BasicUser.Id, th.BasicChannel.Id)
+
+		_, err := th.App.CreatePostAsUser(newPost("files", first.Id, second.Id, third.Id))
+		require.NotNil(t, err)
+		assert.Equal(t, "app.post.check_file_ids.too_many.app_error", err.Id)
+
+		post, err := th.App.CreatePostAsUser(newPost("files", first.Id, second.Id, first.Id))
+		require.Nil(t, err, "duplicate files should only be counted once")
+		assert.Len(t, post.FileIds, 2)
+
+		post.FileIds = model.StringArray{first.Id, second.Id, third.Id}
+		_, err = th.App.UpdatePost(post, false)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.post.check_file_ids.too_many.app_error", err.Id)
+	})
+
+	t.Run("invalid files", func(t *testing.T) {
+		otherChannel := th.CreateChannel(th.BasicTeam)
+
+		for name, fileId := range map[string]string{
+			"missing":         model.NewId(),
+			"another user's":  saveFile(th.BasicUser2.Id, th.BasicChannel.Id).Id,
+			"another channel": saveFile(th.BasicUser.Id, otherChannel.Id).Id,
+		} {
+			_, err := th.App.CreatePostAsUser(newPost("file", fileId))
+			require.NotNil(t, err, name)
+			assert.Equal(t, "app.post.check_file_ids.invalid.app_error", err.Id, name)
+		}
+
+		attached := saveFile(th.BasicUser.Id, th.BasicChannel.Id)
+		_, err := th.App.CreatePostAsUser(newPost("file", attached.Id))
+		require.Nil(t, err)
+
+		_, err = th.App.CreatePostAsUser(newPost("file again", attached.Id))
+		require.NotNil(t, err, "files already attached to a post can't be attached to another")
+		assert.Equal(t, "app.post.check_file_ids.invalid.app_error", err.Id)
+	})
+
+	t.Run("import", func(t *testing.T) {
+		fileIds := []string{saveFile(th.BasicUser.Id, "").Id, saveFile(th.BasicUser.Id, "").Id, saveFile(th.BasicUser.Id, "").Id}
+
+		postId := th.App.OldImportPost(newPost("imported", fileIds...))
+		require.NotEqual(t, "", postId)
+
+		post, err := th.App.GetSinglePost(postId)
+		require.Nil(t, err)
+		assert.Equal(t, model.StringArray(fileIds[:2]), post.FileIds)
+	})
+}
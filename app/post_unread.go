@@ -0,0 +1,342 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const UNREAD_MENTIONS_POSTS_PER_PAGE = 200
+
+// MarkPostAsUnread marks the channel containing the post as only having been read up to just before the post, so
+// that the post and everything after it are unread again, recounting the user's mentions in those posts. Replies
+// to threads that the user follows with collapsed reply threads turned on are tracked by the thread instead, so
+// only the thread is marked as unread for them.
+func (a *App) MarkPostAsUnread(postId string, userId string) (*model.ChannelUnread, *model.AppError) {
+	post, err := a.GetSinglePost(postId)
+	if err != nil {
+		return nil, err
+	}
+
+	channel, err := a.GetChannel(post.ChannelId)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := a.GetUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	counter, err := a.newUnreadMentionCounter(user, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	if post.RootId != "" && a.countsMentionsInThread(userId, post.RootId) {
+		if err := a.markThreadAsUnreadFromPost(post, channel, counter); err != nil {
+			return nil, err
+		}
+
+		return a.GetChannelUnread(channel.Id, userId)
+	}
+
+	mentionCount, err := a.countChannelMentionsFromPost(post, counter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Otherwise a queued view of the channel would move the user's read position forward again when it's written
+	a.flushChannelViewsForUser(userId)
+
+	result := <-a.Srv.Store.Channel().UpdateLastViewedAtPost(post, userId, mentionCount)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	channelUnread := result.Data.(*model.ChannelUnread)
+
+	if channelUnread.NotifyProps[model.MARK_UNREAD_NOTIFY_PROP] == model.CHANNEL_MARK_UNREAD_MENTION {
+		channelUnread.MsgCount = 0
+	}
+
+	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_POST_UNREAD, channel.TeamId, channel.Id, userId, nil)
+	message.Add("post_id", post.Id)
+	message.Add("msg_count", channelUnread.MsgCount)
+	message.Add("mention_count", channelUnread.MentionCount)
+	message.Add("last_viewed_at", channelUnread.LastViewedAt)
+	message.Add("last_read_post_id", channelUnread.LastReadPostId)
+	a.Publish(message)
+
+	return channelUnread, nil
+}
+
+// MarkPostAsRead records that the user has read their channel up to the post, so that every device places the new
+// messages line after it. This is separate from viewing the channel, which clears the unread counts, and never moves
+// the line back if the user has already read a later post on another device.
+func (a *App) MarkPostAsRead(postId string, userId string) (*model.ChannelUnread, *model.AppError) {
+	post, err := a.GetSinglePost(postId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := <-a.Srv.Store.Channel().UpdateLastReadPost(post, userId)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	channelUnread := result.Data.(*model.ChannelUnread)
+
+	if channelUnread.NotifyProps[model.MARK_UNREAD_NOTIFY_PROP] == model.CHANNEL_MARK_UNREAD_MENTION {
+		channelUnread.MsgCount = 0
+	}
+
+	if channelUnread.LastReadPostId == post.Id {
+		message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_POST_READ, channelUnread.TeamId, channelUnread.ChannelId, userId, nil)
+		message.Add("post_id", post.Id)
+		message.Add("last_read_post_id", channelUnread.LastReadPostId)
+		a.Publish(message)
+	}
+
+	return channelUnread, nil
+}
+
+// countChannelMentionsFromPost counts the posts from the given one onwards that mention the user, leaving out
+// replies whose mentions are counted by their threads.
+func (a *App) countChannelMentionsFromPost(post *model.Post, counter *unreadMentionCounter) (int64, *model.AppError) {
+	countedInThread := make(map[string]bool)
+	var count int64
+
+	countPost := func(p *model.Post) {
+		if p.RootId != "" {
+			inThread, ok := countedInThread[p.RootId]
+			if !ok {
+				inThread = a.countsMentionsInThread(counter.user.Id, p.RootId)
+				countedInThread[p.RootId] = inThread
+			}
+
+			if inThread {
+				return
+			}
+		}
+
+		if counter.isMentioned(p) {
+			count++
+		}
+	}
+
+	countPost(post)
+
+	for offset := 0; ; offset += UNREAD_MENTIONS_POSTS_PER_PAGE {
+		result := <-a.Srv.Store.Post().GetPostsAfter(post.ChannelId, post.Id, UNREAD_MENTIONS_POSTS_PER_PAGE, offset)
+		if result.Err != nil {
+			return 0, result.Err
+		}
+		list := result.Data.(*model.PostList)
+
+		// the list also contains the roots of any replies, but only the posts in its order come after the post
+		for _, id := range list.Order {
+			countPost(list.Posts[id])
+		}
+
+		if len(list.Order) < UNREAD_MENTIONS_POSTS_PER_PAGE {
+			return count, nil
+		}
+	}
+}
+
+// removeDeletedPostsFromUnreads takes posts that have just been deleted from a channel out of its members' unread
+// message and mention counts. Members whose mention counts go down are sent their updated membership so that their
+// badges are corrected right away.
+func (a *App) removeDeletedPostsFromUnreads(channelId string, posts []*model.Post) *model.AppError {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	channel, err := a.GetChannel(channelId)
+	if err != nil {
+		return err
+	}
+
+	var newest int64
+	for _, post := range posts {
+		if post.CreateAt > newest {
+			newest = post.CreateAt
+		}
+	}
+
+	// The counts are adjusted based on where each member has read up to
+	a.FlushWriteBehind()
+
+	result := <-a.Srv.Store.Channel().GetMembersWithUnreadMentions(channelId, newest)
+	if result.Err != nil {
+		return result.Err
+	}
+	members := *result.Data.(*model.ChannelMembers)
+
+	mentionCounts := make(map[string]int64)
+	if len(members) > 0 {
+		lastViewedAt := make(map[string]int64, len(members))
+		userIds := make([]string, 0, len(members))
+		for _, member := range members {
+			lastViewedAt[member.UserId] = member.LastViewedAt
+			userIds = append(userIds, member.UserId)
+		}
+
+		uresult := <-a.Srv.Store.User().GetProfileByIds(userIds, true)
+		if uresult.Err != nil {
+			return uresult.Err
+		}
+
+		for _, user := range uresult.Data.([]*model.User) {
+			counter, err := a.newUnreadMentionCounter(user, channel)
+			if err != nil {
+				return err
+			}
+
+			countedInThread := make(map[string]bool)
+			var count int64
+
+			for _, post := range posts {
+				if post.CreateAt <= lastViewedAt[user.Id] {
+					continue
+				}
+
+				if post.RootId != "" {
+					inThread, ok := countedInThread[post.RootId]
+					if !ok {
+						inThread = a.countsMentionsInThread(user.Id, post.RootId)
+						countedInThread[post.RootId] = inThread
+					}
+
+					if inThread {
+						continue
+					}
+				}
+
+				if counter.isMentioned(post) {
+					count++
+				}
+			}
+
+			if count > 0 {
+				mentionCounts[user.Id] = count
+			}
+		}
+	}
+
+	if result := <-a.Srv.Store.Channel().RemovePostsFromCounts(channelId, posts, mentionCounts); result.Err != nil {
+		return result.Err
+	}
+
+	for userId := range mentionCounts {
+		member, err := a.GetChannelMember(channelId, userId)
+		if err != nil {
+			return err
+		}
+
+		evt := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_CHANNEL_MEMBER_UPDATED, "", "", userId, nil)
+		evt.Add("channelMember", member.ToJson())
+		a.Publish(evt)
+	}
+
+	return nil
+}
+
+// markThreadAsUnreadFromPost marks the thread containing the reply as only having been read up to just before it.
+func (a *App) markThreadAsUnreadFromPost(post *model.Post, channel *model.Channel, counter *unreadMentionCounter) *model.AppError {
+	result := <-a.Srv.Store.Thread().GetMembershipForUser(counter.user.Id, post.RootId)
+	if result.Err != nil {
+		return result.Err
+	}
+	membership := result.Data.(*model.ThreadMembership)
+
+	presult := <-a.Srv.Store.Post().Get(post.RootId)
+	if presult.Err != nil {
+		return presult.Err
+	}
+
+	var mentionCount int64
+	for _, reply := range threadPostsFromList(presult.Data.(*model.PostList))[1:] {
+		if reply.CreateAt >= post.CreateAt && counter.isMentioned(reply) {
+			mentionCount++
+		}
+	}
+
+	membership.LastViewed = post.CreateAt - 1
+	membership.LastUpdated = model.GetMillis()
+	membership.UnreadMentions = mentionCount
+
+	if result := <-a.Srv.Store.Thread().UpdateMembership(membership); result.Err != nil {
+		return result.Err
+	}
+
+	_, err := a.publishThreadUpdatedForUser(counter.user.Id, channel.TeamId, post.RootId)
+	return err
+}
+
+// unreadMentionCounter decides which existing posts in a channel would have mentioned a user in the same way as
+// notifications do when a post is made, except that @here is based on whether they're online now.
+type unreadMentionCounter struct {
+	a       *App
+	user    *model.User
+	channel *model.Channel
+
+	keywords                map[string][]string
+	keywordsWithoutSpecial  map[string][]string
+	channelMentionsAllowed  bool
+	senderCanMentionChannel map[string]bool
+}
+
+func (a *App) newUnreadMentionCounter(user *model.User, channel *model.Channel) (*unreadMentionCounter, *model.AppError) {
+	memberCount, err := a.GetChannelMemberCount(channel.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := map[string]*model.User{user.Id: user}
+
+	return &unreadMentionCounter{
+		a:                       a,
+		user:                    user,
+		channel:                 channel,
+		keywords:                a.GetMentionKeywordsInChannel(profiles, true),
+		keywordsWithoutSpecial:  a.GetMentionKeywordsInChannel(profiles, false),
+		channelMentionsAllowed:  !channel.DisableChannelMentions && memberCount <= *a.Config().TeamSettings.MaxNotificationsPerChannel,
+		senderCanMentionChannel: make(map[string]bool),
+	}, nil
+}
+
+func (c *unreadMentionCounter) isMentioned(post *model.Post) bool {
+	if post.UserId == c.user.Id && post.Props["from_webhook"] != "true" {
+		return false
+	}
+
+	if c.channel.Type == model.CHANNEL_DIRECT {
+		return true
+	}
+
+	if post.Type == model.POST_ADD_TO_CHANNEL && post.Props[model.POST_PROPS_ADDED_USER_ID] == c.user.Id {
+		return true
+	}
+
+	keywords := c.keywordsWithoutSpecial
+	if post.Type != model.POST_HEADER_CHANGE && post.Type != model.POST_PURPOSE_CHANGE && c.canMentionChannel(post.UserId) {
+		keywords = c.keywords
+	}
+
+	return GetExplicitMentions(post.Message, keywords).MentionedUserIds[c.user.Id]
+}
+
+func (c *unreadMentionCounter) canMentionChannel(senderId string) bool {
+	if !c.channelMentionsAllowed {
+		return false
+	}
+
+	allowed, ok := c.senderCanMentionChannel[senderId]
+	if !ok {
+		allowed = !c.a.userIsModeratedInChannel(senderId, c.channel.Id, model.CHANNEL_MODERATED_PERMISSION_USE_CHANNEL_MENTIONS)
+		c.senderCanMentionChannel[senderId] = allowed
+	}
+
+	return allowed
+}
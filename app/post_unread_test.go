@@ -0,0 +1,323 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestMarkPostAsUnread(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	post := func(user *model.User, channel *model.Channel, message string) *model.Post {
+		// Make sure that each post is created at a different time than the last
+		time.Sleep(time.Millisecond)
+
+		created, err := th.App.CreatePost(&model.Post{UserId: user.Id, ChannelId: channel.Id, Message: message}, channel, false)
+		require.Nil(t, err)
+		return created
+	}
+
+	t.Run("recounts messages and mentions", func(t *testing.T) {
+		channel := th.CreateChannel(th.BasicTeam)
+		th.AddUserToChannel(th.BasicUser2, channel)
+
+		first := post(th.BasicUser2, channel, "first")
+		mention := post(th.BasicUser2, channel, "hello @"+th.BasicUser.Username)
+		own := post(th.BasicUser, channel, "my own message")
+		post(th.BasicUser2, channel, "last")
+
+		_, err := th.App.ViewChannel(&model.ChannelView{ChannelId: channel.Id}, th.BasicUser.Id, false)
+		require.Nil(t, err)
+
+		unread, err := th.App.MarkPostAsUnread(mention.Id, th.BasicUser.Id)
+		require.Nil(t, err)
+		assert.Equal(t, channel.Id, unread.ChannelId)
+		assert.Equal(t, int64(3), unread.MsgCount)
+		assert.Equal(t, int64(1), unread.MentionCount)
+		assert.Equal(t, mention.CreateAt-1, unread.LastViewedAt)
+		assert.Equal(t, first.Id, unread.LastReadPostId)
+
+		member, err := th.App.GetChannelMember(channel.Id, th.BasicUser.Id)
+		require.Nil(t, err)
+		assert.Equal(t, int64(1), member.MentionCount)
+		assert.Equal(t, mention.CreateAt-1, member.LastViewedAt)
+
+		// the first post in the channel leaves the whole channel unread
+		unread, err = th.App.MarkPostAsUnread(first.Id, th.BasicUser.Id)
+		require.Nil(t, err)
+		assert.Equal(t, int64(4), unread.MsgCount)
+		assert.Equal(t, int64(1), unread.MentionCount)
+
+		// marking a later post unread forgets about the earlier mention
+		unread, err = th.App.MarkPostAsUnread(own.Id, th.BasicUser.Id)
+		require.Nil(t, err)
+		assert.Equal(t, int64(2), unread.MsgCount)
+		assert.Equal(t, int64(0), unread.MentionCount)
+
+		mentionedAgain := post(th.BasicUser2, channel, "@"+th.BasicUser.Username+" again")
+		unread, err = th.App.MarkPostAsUnread(mentionedAgain.Id, th.BasicUser.Id)
+		require.Nil(t, err)
+		assert.Equal(t, int64(1), unread.MsgCount)
+		assert.Equal(t, int64(1), unread.MentionCount)
+
+		_, err = th.App.ViewChannel(&model.ChannelView{ChannelId: channel.Id}, th.BasicUser.Id, false)
+		require.Nil(t, err)
+
+		unread, err = th.App.GetChannelUnread(channel.Id, th.BasicUser.Id)
+		require.Nil(t, err)
+		assert.Equal(t, int64(0), unread.MsgCount)
+		assert.Equal(t, int64(0), unread.MentionCount)
+	})
+
+	t.Run("every message in a direct channel is a mention", func(t *testing.T) {
+		dm := th.CreateDmChannel(th.BasicUser2)
+
+		first := post(th.BasicUser2, dm, "first")
+		post(th.BasicUser, dm, "reply")
+		post(th.BasicUser2, dm, "second")
+
+		unread, err := th.App.MarkPostAsUnread(first.Id, th.BasicUser.Id)
+		require.Nil(t, err)
+		assert.Equal(t, int64(3), unread.MsgCount)
+		assert.Equal(t, int64(2), unread.MentionCount)
+	})
+
+	t.Run("replies in followed threads are marked unread in the thread", func(t *testing.T) {
+		th.AddUserToChannel(th.BasicUser2, th.BasicChannel)
+		require.Nil(t, th.App.UpdatePreferences(th.BasicUser.Id, model.Preferences{{
+			UserId:   th.BasicUser.Id,
+			Category: model.PREFERENCE_CATEGORY_DISPLAY_SETTINGS,
+			Name:     model.PREFERENCE_NAME_COLLAPSED_THREADS,
+			Value:    model.COLLAPSED_THREADS_ON,
+		}}))
+
+		root := th.BasicPost
+		reply1 := th.createReply(th.BasicUser2, root, "first reply")
+		th.createReply(th.BasicUser2, root, "@"+th.BasicUser.Username+" second reply")
+		th.createReply(th.BasicUser, root, "my reply")
+
+		_, err := th.App.ViewChannel(&model.ChannelView{ChannelId: th.BasicChannel.Id}, th.BasicUser.Id, false)
+		require.Nil(t, err)
+
+		unread, err := th.App.MarkPostAsUnread(reply1.Id, th.BasicUser.Id)
+		require.Nil(t, err)
+		assert.Equal(t, int64(0), unread.MsgCount, "the channel should be left alone")
+		assert.Equal(t, int64(0), unread.MentionCount)
+
+		thread, err := th.App.GetThreadForUser(th.BasicUser.Id, th.BasicTeam.Id, root.Id)
+		require.Nil(t, err)
+		assert.Equal(t, reply1.CreateAt-1, thread.LastViewedAt)
+		assert.Equal(t, int64(3), thread.UnreadReplies)
+		assert.Equal(t, int64(1), thread.UnreadMentions)
+
+		// the root post isn't part of the thread's unread replies, so the channel is marked unread for it
+		unread, err = th.App.MarkPostAsUnread(root.Id, th.BasicUser.Id)
+		require.Nil(t, err)
+		assert.Equal(t, root.CreateAt-1, unread.LastViewedAt)
+		assert.Equal(t, int64(0), unread.MentionCount, "mentions in the followed thread are counted by the thread")
+	})
+
+	t.Run("deleted post", func(t *testing.T) {
+		deleted := post(th.BasicUser2, th.BasicChannel, "deleted")
+		_, err := th.App.DeletePost(deleted.Id)
+		require.Nil(t, err)
+
+		_, err = th.App.MarkPostAsUnread(deleted.Id, th.BasicUser.Id)
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusNotFound, err.StatusCode)
+	})
+}
+
+func TestMarkPostAsRead(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	post := func(user *model.User, channel *model.Channel, message string) *model.Post {
+		// Make sure that each post is created at a different time than the last
+		time.Sleep(time.Millisecond)
+
+		created, err := th.App.CreatePost(&model.Post{UserId: user.Id, ChannelId: channel.Id, Message: message}, channel, false)
+		require.Nil(t, err)
+		return created
+	}
+
+	t.Run("interleaved reads only move forward", func(t *testing.T) {
+		channel := th.CreateChannel(th.BasicTeam)
+		th.AddUserToChannel(th.BasicUser2, channel)
+
+		first := post(th.BasicUser2, channel, "first")
+		second := post(th.BasicUser2, channel, "second")
+		third := post(th.BasicUser2, channel, "third")
+
+		// one device reads further than another that's still behind
+		unread, err := th.App.MarkPostAsRead(first.Id, th.BasicUser.Id)
+		require.Nil(t, err)
+		assert.Equal(t, first.Id, unread.LastReadPostId)
+
+		unread, err = th.App.MarkPostAsRead(third.Id, th.BasicUser.Id)
+		require.Nil(t, err)
+		assert.Equal(t, third.Id, unread.LastReadPostId)
+
+		unread, err = th.App.MarkPostAsRead(second.Id, th.BasicUser.Id)
+		require.Nil(t, err)
+		assert.Equal(t, third.Id, unread.LastReadPostId, "reading an earlier post shouldn't move the line back")
+
+		// reading doesn't view the channel
+		assert.NotZero(t, unread.MsgCount)
+
+		unread, err = th.App.GetChannelUnread(channel.Id, th.BasicUser.Id)
+		require.Nil(t, err)
+		assert.Equal(t, third.Id, unread.LastReadPostId)
+	})
+
+	t.Run("marking a post as unread moves the line back", func(t *testing.T) {
+		channel := th.CreateChannel(th.BasicTeam)
+		th.AddUserToChannel(th.BasicUser2, channel)
+
+		first := post(th.BasicUser2, channel, "first")
+		second := post(th.BasicUser2, channel, "second")
+		third := post(th.BasicUser2, channel, "third")
+
+		_, err := th.App.MarkPostAsRead(third.Id, th.BasicUser.Id)
+		require.Nil(t, err)
+
+		unread, err := th.App.MarkPostAsUnread(second.Id, th.BasicUser.Id)
+		require.Nil(t, err)
+		assert.Equal(t, first.Id, unread.LastReadPostId)
+
+		// reading can move it forward again afterwards
+		unread, err = th.App.MarkPostAsRead(second.Id, th.BasicUser.Id)
+		require.Nil(t, err)
+		assert.Equal(t, second.Id, unread.LastReadPostId)
+
+		unread, err = th.App.MarkPostAsUnread(first.Id, th.BasicUser.Id)
+		require.Nil(t, err)
+		assert.Equal(t, "", unread.LastReadPostId)
+	})
+
+	t.Run("marking every channel as read moves the line to the latest post", func(t *testing.T) {
+		channel := th.CreateChannel(th.BasicTeam)
+		th.AddUserToChannel(th.BasicUser2, channel)
+
+		first := post(th.BasicUser2, channel, "first")
+		last := post(th.BasicUser2, channel, "last")
+
+		_, err := th.App.MarkPostAsRead(first.Id, th.BasicUser.Id)
+		require.Nil(t, err)
+
+		_, err = th.App.MarkChannelsAsViewed(th.BasicUser.Id, th.BasicTeam.Id, false)
+		require.Nil(t, err)
+
+		unread, err := th.App.GetChannelUnread(channel.Id, th.BasicUser.Id)
+		require.Nil(t, err)
+		assert.Equal(t, last.Id, unread.LastReadPostId)
+	})
+
+	t.Run("not a member", func(t *testing.T) {
+		channel := th.CreateChannel(th.BasicTeam)
+		other := post(th.BasicUser, channel, "other")
+
+		_, err := th.App.MarkPostAsRead(other.Id, th.BasicUser2.Id)
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusNotFound, err.StatusCode)
+	})
+}
+
+func TestDeletePostUpdatesUnreads(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	post := func(user *model.User, channel *model.Channel, rootId string, message string) *model.Post {
+		// Make sure that each post is created at a different time than the last
+		time.Sleep(time.Millisecond)
+
+		created, err := th.App.CreatePost(&model.Post{UserId: user.Id, ChannelId: channel.Id, RootId: rootId, Message: message}, channel, false)
+		require.Nil(t, err)
+		return created
+	}
+
+	view := func(user *model.User, channel *model.Channel) {
+		_, err := th.App.ViewChannel(&model.ChannelView{ChannelId: channel.Id}, user.Id, false)
+		require.Nil(t, err)
+	}
+
+	assertUnread := func(user *model.User, channel *model.Channel, msgCount int64, mentionCount int64) {
+		unread, err := th.App.GetChannelUnread(channel.Id, user.Id)
+		require.Nil(t, err)
+		assert.Equal(t, msgCount, unread.MsgCount, "wrong unread messages for %v", user.Username)
+		assert.Equal(t, mentionCount, unread.MentionCount, "wrong mentions for %v", user.Username)
+	}
+
+	t.Run("members with different read positions", func(t *testing.T) {
+		channel := th.CreateChannel(th.BasicTeam)
+		th.AddUserToChannel(th.BasicUser2, channel)
+
+		other := th.CreateUser()
+		th.LinkUserToTeam(other, th.BasicTeam)
+		th.AddUserToChannel(other, channel)
+
+		view(th.BasicUser, channel)
+		view(other, channel)
+
+		mention := post(th.BasicUser2, channel, "", "hello @"+th.BasicUser.Username+" and @"+other.Username)
+		post(th.BasicUser2, channel, "", "not a mention")
+
+		// other has read both posts while BasicUser hasn't read either
+		view(other, channel)
+		assertUnread(th.BasicUser, channel, 2, 1)
+		assertUnread(other, channel, 0, 0)
+
+		_, err := th.App.DeletePost(mention.Id)
+		require.Nil(t, err)
+
+		assertUnread(th.BasicUser, channel, 1, 0)
+		assertUnread(other, channel, 0, 0)
+
+		member, err := th.App.GetChannelMember(channel.Id, other.Id)
+		require.Nil(t, err)
+		assert.Equal(t, int64(0), member.MentionCount)
+	})
+
+	t.Run("deleting a root post removes its replies from the counts", func(t *testing.T) {
+		channel := th.CreateChannel(th.BasicTeam)
+		th.AddUserToChannel(th.BasicUser2, channel)
+		view(th.BasicUser, channel)
+
+		root := post(th.BasicUser2, channel, "", "@"+th.BasicUser.Username+" root")
+		post(th.BasicUser2, channel, root.Id, "@"+th.BasicUser.Username+" reply")
+		reply := post(th.BasicUser2, channel, root.Id, "@"+th.BasicUser.Username+" another reply")
+		post(th.BasicUser2, channel, "", "@"+th.BasicUser.Username+" unrelated")
+		assertUnread(th.BasicUser, channel, 4, 4)
+
+		_, err := th.App.DeletePost(reply.Id)
+		require.Nil(t, err)
+		assertUnread(th.BasicUser, channel, 3, 3)
+
+		_, err = th.App.DeletePost(root.Id)
+		require.Nil(t, err)
+		assertUnread(th.BasicUser, channel, 1, 1)
+	})
+
+	t.Run("every message in a direct channel is a mention", func(t *testing.T) {
+		dm := th.CreateDmChannel(th.BasicUser2)
+		view(th.BasicUser, dm)
+
+		first := post(th.BasicUser2, dm, "", "first")
+		post(th.BasicUser2, dm, "", "second")
+		assertUnread(th.BasicUser, dm, 2, 2)
+
+		_, err := th.App.DeletePost(first.Id)
+		require.Nil(t, err)
+		assertUnread(th.BasicUser, dm, 1, 1)
+	})
+}
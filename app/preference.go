@@ -9,6 +9,13 @@ import (
 	"github.com/mattermost/mattermost-server/model"
 )
 
+const (
+	PREFERENCES_CLEANUP_BATCH_SIZE = 1000
+
+	// Preferences are sent to clients in as few events as keep each under this many bytes of JSON
+	PREFERENCES_EVENT_MAX_SIZE = 32 * 1024
+)
+
 func (a *App) GetPreferencesForUser(userId string) (model.Preferences, *model.AppError) {
 	if result := <-a.Srv.Store.Preference().GetAll(userId); result.Err != nil {
 		result.Err.StatusCode = http.StatusBadRequest
@@ -40,12 +47,31 @@ func (a *App) GetPreferenceByCategoryAndNameForUser(userId string, category stri
 	}
 }
 
+func (a *App) GetPreferencesPageForUser(userId string, category string, page int, perPage int) (model.Preferences, *model.AppError) {
+	if result := <-a.Srv.Store.Preference().GetPage(userId, category, page*perPage, perPage); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return result.Data.(model.Preferences), nil
+	}
+}
+
+// UpdatePreferences saves up to model.PREFERENCES_MAX_UPDATE preferences for the user. Either all of them are
+// saved or, if any is invalid or in a category unknown to the server, none are.
 func (a *App) UpdatePreferences(userId string, preferences model.Preferences) *model.AppError {
+	if len(preferences) > model.PREFERENCES_MAX_UPDATE {
+		return model.NewAppError("savePreferences", "api.preference.update_preferences.too_many.app_error", map[string]interface{}{"Max": model.PREFERENCES_MAX_UPDATE},
+			"userId="+userId, http.StatusBadRequest)
+	}
+
 	for _, preference := range preferences {
 		if userId != preference.UserId {
 			return model.NewAppError("savePreferences", "api.preference.update_preferences.set.app_error", nil,
 				"userId="+userId+", preference.UserId="+preference.UserId, http.StatusForbidden)
 		}
+
+		if err := preference.IsValidForClient(); err != nil {
+			return err
+		}
 	}
 
 	if result := <-a.Srv.Store.Preference().Save(&preferences); result.Err != nil {
@@ -53,9 +79,7 @@ func (a *App) UpdatePreferences(userId string, preferences model.Preferences) *m
 		return result.Err
 	}
 
-	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_PREFERENCES_CHANGED, "", "", userId, nil)
-	message.Add("preferences", preferences.ToJson())
-	a.Publish(message)
+	a.PublishPreferencesEvent(model.WEBSOCKET_EVENT_PREFERENCES_CHANGED, userId, preferences)
 
 	return nil
 }
@@ -76,9 +100,36 @@ func (a *App) DeletePreferences(userId string, preferences model.Preferences) *m
 		}
 	}
 
-	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_PREFERENCES_DELETED, "", "", userId, nil)
-	message.Add("preferences", preferences.ToJson())
-	a.Publish(message)
+	a.PublishPreferencesEvent(model.WEBSOCKET_EVENT_PREFERENCES_DELETED, userId, preferences)
 
 	return nil
 }
+
+// PublishPreferencesEvent sends the user an event of the given type, either preferences_changed or
+// preferences_deleted, with the preferences. They're split over more than one event only when there are too many to
+// fit in one.
+func (a *App) PublishPreferencesEvent(eventType string, userId string, preferences model.Preferences) {
+	for _, group := range preferences.SplitByJsonSize(PREFERENCES_EVENT_MAX_SIZE) {
+		message := model.NewWebSocketEvent(eventType, "", "", userId, nil)
+		message.Add("preferences", group.ToJson())
+		a.Publish(message)
+	}
+}
+
+// CleanupOrphanedChannelPreferences deletes the preferences that refer to channels which no longer exist, and
+// returns how many were deleted.
+func (a *App) CleanupOrphanedChannelPreferences() (int64, *model.AppError) {
+	var deleted int64
+	for {
+		result := <-a.Srv.Store.Preference().CleanupChannelsBatch(PREFERENCES_CLEANUP_BATCH_SIZE)
+		if result.Err != nil {
+			return deleted, result.Err
+		}
+
+		rowsAffected := result.Data.(int64)
+		deleted += rowsAffected
+		if rowsAffected < PREFERENCES_CLEANUP_BATCH_SIZE {
+			return deleted, nil
+		}
+	}
+}
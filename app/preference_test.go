@@ -0,0 +1,49 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestCleanupOrphanedChannelPreferences(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	user := th.BasicUser
+	kept := th.CreateChannel(th.BasicTeam)
+	deleted := th.CreateChannel(th.BasicTeam)
+	archived := th.CreateChannel(th.BasicTeam)
+
+	require.Nil(t, th.App.UpdatePreferences(user.Id, model.Preferences{
+		{UserId: user.Id, Category: model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, Name: kept.Id, Value: "true"},
+		{UserId: user.Id, Category: model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, Name: deleted.Id, Value: "true"},
+		{UserId: user.Id, Category: model.PREFERENCE_CATEGORY_CHANNEL_OPEN_TIME, Name: deleted.Id, Value: "1000"},
+		{UserId: user.Id, Category: model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, Name: archived.Id, Value: "true"},
+	}))
+
+	require.Nil(t, th.App.PermanentDeleteChannel(deleted))
+	require.Nil(t, th.App.DeleteChannel(archived, user.Id))
+
+	count, err := th.App.CleanupOrphanedChannelPreferences()
+	require.Nil(t, err)
+	assert.True(t, count >= 2)
+
+	_, err = th.App.GetPreferenceByCategoryAndNameForUser(user.Id, model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, kept.Id)
+	assert.Nil(t, err)
+
+	_, err = th.App.GetPreferenceByCategoryAndNameForUser(user.Id, model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, deleted.Id)
+	assert.NotNil(t, err)
+
+	_, err = th.App.GetPreferenceByCategoryAndNameForUser(user.Id, model.PREFERENCE_CATEGORY_CHANNEL_OPEN_TIME, deleted.Id)
+	assert.NotNil(t, err)
+
+	_, err = th.App.GetPreferenceByCategoryAndNameForUser(user.Id, model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, archived.Id)
+	assert.Nil(t, err, "archived channels can be restored, so their preferences should be kept")
+}
@@ -0,0 +1,219 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store/sqlstore"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+const (
+	PREFLIGHT_CHECK_DATABASE                = "Database"
+	PREFLIGHT_CHECK_DATABASE_SCHEMA         = "Database schema"
+	PREFLIGHT_CHECK_FILE_STORAGE            = "File storage"
+	PREFLIGHT_CHECK_SITE_URL                = "Site URL"
+	PREFLIGHT_CHECK_SMTP                    = "SMTP"
+	PREFLIGHT_CHECK_LISTEN_ADDRESS          = "Listen address"
+	PREFLIGHT_CHECK_LOG_DIRECTORY           = "Log directory"
+	PREFLIGHT_CHECK_PLUGIN_DIRECTORY        = "Plugin directory"
+	PREFLIGHT_CHECK_PLUGIN_CLIENT_DIRECTORY = "Plugin client directory"
+
+	PREFLIGHT_LOOKUP_TIMEOUT = 5 * time.Second
+)
+
+// RunPreflightChecks checks that the server can be started with the config, and that the services it points to can be
+// used, without starting anything. It's meant to be run before the server is started so that a misconfigured server
+// refuses to start with the reason why instead of failing somewhere down the line.
+func RunPreflightChecks(config *model.Config) *model.PreflightReport {
+	report := &model.PreflightReport{}
+
+	checkPreflightDatabase(report, config)
+
+	if backend, err := utils.NewFileBackend(&config.FileSettings, false); err != nil {
+		report.Add(PREFLIGHT_CHECK_FILE_STORAGE, model.PREFLIGHT_STATUS_FAIL, fmt.Sprintf("Unable to set up the %v file storage: %v. Check FileSettings.", *config.FileSettings.DriverName, err.Error()))
+	} else {
+		checkPreflightFileBackend(report, backend)
+	}
+
+	checkPreflightSiteURL(report, config)
+	checkPreflightSMTP(report, config)
+	checkPreflightListenAddress(report, *config.ServiceSettings.ListenAddress)
+
+	if config.LogSettings.EnableFile {
+		checkPreflightDirectory(report, PREFLIGHT_CHECK_LOG_DIRECTORY, filepath.Dir(utils.GetLogFileLocation(config.LogSettings.FileLocation)), model.PREFLIGHT_STATUS_FAIL)
+	}
+
+	// Plugins are turned off when their directories can't be used, but the rest of the server still runs without them
+	if *config.PluginSettings.Enable {
+		checkPreflightDirectory(report, PREFLIGHT_CHECK_PLUGIN_DIRECTORY, *config.PluginSettings.Directory, model.PREFLIGHT_STATUS_WARN)
+		checkPreflightDirectory(report, PREFLIGHT_CHECK_PLUGIN_CLIENT_DIRECTORY, *config.PluginSettings.ClientDirectory, model.PREFLIGHT_STATUS_WARN)
+	}
+
+	return report
+}
+
+func checkPreflightDatabase(report *model.PreflightReport, config *model.Config) {
+	version, err := sqlstore.CheckConnection(&config.SqlSettings)
+	if err != nil {
+		report.Add(PREFLIGHT_CHECK_DATABASE, model.PREFLIGHT_STATUS_FAIL, fmt.Sprintf("Unable to connect to the %v database: %v. Check that the database is running and that SqlSettings.DataSource is correct.", *config.SqlSettings.DriverName, err.Error()))
+		return
+	}
+	report.Add(PREFLIGHT_CHECK_DATABASE, model.PREFLIGHT_STATUS_PASS, fmt.Sprintf("Connected to the %v database", *config.SqlSettings.DriverName))
+
+	switch {
+	case version == "":
+		report.Add(PREFLIGHT_CHECK_DATABASE_SCHEMA, model.PREFLIGHT_STATUS_PASS, "The database is empty, so the schema will be created")
+	case compareVersions(version, model.CurrentVersion) > 0:
+		report.Add(PREFLIGHT_CHECK_DATABASE_SCHEMA, model.PREFLIGHT_STATUS_FAIL, fmt.Sprintf("The database schema is at version %v, which is newer than this server's version %v. Downgrades aren't supported, so run a newer server or restore a backup of the database.", version, model.CurrentVersion))
+	case compareVersions(version, sqlstore.OLDEST_SUPPORTED_VERSION) < 0:
+		report.Add(PREFLIGHT_CHECK_DATABASE_SCHEMA, model.PREFLIGHT_STATUS_FAIL, fmt.Sprintf("The database schema is at version %v, which is too old to be upgraded. Upgrade to at least version %v with an older server first.", version, sqlstore.OLDEST_SUPPORTED_VERSION))
+	case version != model.CurrentVersion:
+		report.Add(PREFLIGHT_CHECK_DATABASE_SCHEMA, model.PREFLIGHT_STATUS_PASS, fmt.Sprintf("The database schema will be upgraded from version %v to %v", version, model.CurrentVersion))
+	default:
+		report.Add(PREFLIGHT_CHECK_DATABASE_SCHEMA, model.PREFLIGHT_STATUS_PASS, fmt.Sprintf("The database schema is at version %v", version))
+	}
+}
+
+// compareVersions returns a negative number if a is an older version than b, a positive one if it's newer, and 0 if
+// they're the same.
+func compareVersions(a string, b string) int64 {
+	aMajor, aMinor, aPatch := model.SplitVersion(a)
+	bMajor, bMinor, bPatch := model.SplitVersion(b)
+
+	if aMajor != bMajor {
+		return aMajor - bMajor
+	}
+	if aMinor != bMinor {
+		return aMinor - bMinor
+	}
+	return aPatch - bPatch
+}
+
+// checkPreflightFileBackend writes a file to the file storage, reads it back, and removes it.
+func checkPreflightFileBackend(report *model.PreflightReport, backend utils.FileBackend) {
+	path := "preflight_" + model.NewId()
+	data := []byte("Mattermost preflight check")
+
+	if _, err := backend.WriteFile(bytes.NewReader(data), path); err != nil {
+		report.Add(PREFLIGHT_CHECK_FILE_STORAGE, model.PREFLIGHT_STATUS_FAIL, fmt.Sprintf("Unable to write to the file storage: %v. Check FileSettings and that the server is allowed to write to the storage.", err.Error()))
+		return
+	}
+
+	read, err := backend.ReadFile(path)
+	removeErr := backend.RemoveFile(path)
+
+	switch {
+	case err != nil:
+		report.Add(PREFLIGHT_CHECK_FILE_STORAGE, model.PREFLIGHT_STATUS_FAIL, fmt.Sprintf("Unable to read back a file written to the file storage: %v", err.Error()))
+	case !bytes.Equal(read, data):
+		report.Add(PREFLIGHT_CHECK_FILE_STORAGE, model.PREFLIGHT_STATUS_FAIL, "A file read back from the file storage didn't match what was written to it")
+	case removeErr != nil:
+		report.Add(PREFLIGHT_CHECK_FILE_STORAGE, model.PREFLIGHT_STATUS_FAIL, fmt.Sprintf("Unable to remove a file from the file storage: %v. Check that the server is allowed to delete from the storage.", removeErr.Error()))
+	default:
+		report.Add(PREFLIGHT_CHECK_FILE_STORAGE, model.PREFLIGHT_STATUS_PASS, "Wrote, read and removed a file")
+	}
+}
+
+func checkPreflightSiteURL(report *model.PreflightReport, config *model.Config) {
+	siteURL := *config.ServiceSettings.SiteURL
+	if siteURL == "" {
+		if model.BuildNumber == "dev" {
+			report.Add(PREFLIGHT_CHECK_SITE_URL, model.PREFLIGHT_STATUS_WARN, "ServiceSettings.SiteURL isn't set, so http://localhost:8065 will be used by this development build")
+		} else {
+			report.Add(PREFLIGHT_CHECK_SITE_URL, model.PREFLIGHT_STATUS_FAIL, "ServiceSettings.SiteURL must be set to the URL that users reach the server at")
+		}
+		return
+	}
+
+	u, err := url.Parse(siteURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		report.Add(PREFLIGHT_CHECK_SITE_URL, model.PREFLIGHT_STATUS_FAIL, fmt.Sprintf("ServiceSettings.SiteURL %v isn't an http or https URL", siteURL))
+		return
+	}
+
+	if *config.ServiceSettings.ConnectionSecurity == model.CONN_SECURITY_TLS && u.Scheme != "https" {
+		report.Add(PREFLIGHT_CHECK_SITE_URL, model.PREFLIGHT_STATUS_WARN, fmt.Sprintf("The server uses TLS, but ServiceSettings.SiteURL %v doesn't use https, so links to the server won't work", siteURL))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), PREFLIGHT_LOOKUP_TIMEOUT)
+	defer cancel()
+	if _, err := net.DefaultResolver.LookupHost(ctx, u.Hostname()); err != nil {
+		report.Add(PREFLIGHT_CHECK_SITE_URL, model.PREFLIGHT_STATUS_WARN, fmt.Sprintf("Unable to look up %v: %v. Users won't be able to reach the server unless it can be found in their DNS.", u.Hostname(), err.Error()))
+		return
+	}
+
+	report.Add(PREFLIGHT_CHECK_SITE_URL, model.PREFLIGHT_STATUS_PASS, siteURL)
+}
+
+// checkPreflightSMTP connects to the SMTP server if email is turned on. It only fails if users need to verify their
+// email addresses, since they can't sign up at all without it.
+func checkPreflightSMTP(report *model.PreflightReport, config *model.Config) {
+	settings := config.EmailSettings
+	if !settings.RequireEmailVerification && !settings.SendEmailNotifications {
+		return
+	}
+
+	status := model.PREFLIGHT_STATUS_WARN
+	if settings.RequireEmailVerification {
+		status = model.PREFLIGHT_STATUS_FAIL
+	}
+
+	conn, err := utils.ConnectToSMTPServer(config)
+	if err != nil {
+		report.Add(PREFLIGHT_CHECK_SMTP, status, fmt.Sprintf("Unable to connect to %v:%v: %v. Check EmailSettings.", settings.SMTPServer, settings.SMTPPort, err.Error()))
+		return
+	}
+	defer conn.Close()
+
+	client, err := utils.NewSMTPClient(conn, config)
+	if err != nil {
+		report.Add(PREFLIGHT_CHECK_SMTP, status, fmt.Sprintf("Unable to sign in to %v:%v: %v. Check EmailSettings.", settings.SMTPServer, settings.SMTPPort, err.Error()))
+		return
+	}
+	client.Quit()
+
+	report.Add(PREFLIGHT_CHECK_SMTP, model.PREFLIGHT_STATUS_PASS, fmt.Sprintf("Connected to %v:%v", settings.SMTPServer, settings.SMTPPort))
+}
+
+func checkPreflightListenAddress(report *model.PreflightReport, address string) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		report.Add(PREFLIGHT_CHECK_LISTEN_ADDRESS, model.PREFLIGHT_STATUS_FAIL, fmt.Sprintf("Unable to listen on %v: %v. Check that another server isn't already using the port, or change ServiceSettings.ListenAddress.", address, err.Error()))
+		return
+	}
+	listener.Close()
+
+	report.Add(PREFLIGHT_CHECK_LISTEN_ADDRESS, model.PREFLIGHT_STATUS_PASS, address)
+}
+
+// checkPreflightDirectory checks that the directory can be written to, creating it if it doesn't exist yet like the
+// server would.
+func checkPreflightDirectory(report *model.PreflightReport, name string, dir string, failureStatus string) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		report.Add(name, failureStatus, fmt.Sprintf("Unable to create %v: %v", dir, err.Error()))
+		return
+	}
+
+	file, err := ioutil.TempFile(dir, "preflight")
+	if err != nil {
+		report.Add(name, failureStatus, fmt.Sprintf("Unable to write to %v: %v. Check that the user running the server is allowed to write to it.", dir, err.Error()))
+		return
+	}
+	file.Close()
+	os.Remove(file.Name())
+
+	report.Add(name, model.PREFLIGHT_STATUS_PASS, dir)
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+// readOnlyFileBackend is file storage that refuses to be written to.
+type readOnlyFileBackend struct {
+	utils.FileBackend
+}
+
+func (b *readOnlyFileBackend) WriteFile(fr io.Reader, path string) (int64, *model.AppError) {
+	return 0, model.NewAppError("WriteFile", "utils.file.write_file.local.app_error", nil, "read-only file system", http.StatusInternalServerError)
+}
+
+func TestPreflightFileBackend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	backend, appErr := utils.NewFileBackend(&model.FileSettings{DriverName: model.NewString(model.IMAGE_DRIVER_LOCAL), Directory: dir}, false)
+	require.Nil(t, appErr)
+
+	report := &model.PreflightReport{}
+	checkPreflightFileBackend(report, backend)
+	require.Len(t, report.Checks, 1)
+	assert.Equal(t, model.PREFLIGHT_STATUS_PASS, report.Checks[0].Status)
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 0, "the file written by the check should be removed")
+
+	report = &model.PreflightReport{}
+	checkPreflightFileBackend(report, &readOnlyFileBackend{backend})
+	require.Len(t, report.Checks, 1)
+	assert.Equal(t, model.PREFLIGHT_STATUS_FAIL, report.Checks[0].Status)
+	assert.Contains(t, report.Checks[0].Message, "read-only file system")
+}
+
+func TestPreflightListenAddress(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	address := listener.Addr().String()
+
+	report := &model.PreflightReport{}
+	checkPreflightListenAddress(report, address)
+	require.Len(t, report.Checks, 1)
+	assert.Equal(t, model.PREFLIGHT_STATUS_FAIL, report.Checks[0].Status, "the port is already in use")
+
+	listener.Close()
+
+	report = &model.PreflightReport{}
+	checkPreflightListenAddress(report, address)
+	require.Len(t, report.Checks, 1)
+	assert.Equal(t, model.PREFLIGHT_STATUS_PASS, report.Checks[0].Status)
+}
+
+func TestPreflightDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	report := &model.PreflightReport{}
+	checkPreflightDirectory(report, PREFLIGHT_CHECK_PLUGIN_DIRECTORY, filepath.Join(dir, "plugins"), model.PREFLIGHT_STATUS_WARN)
+	require.Len(t, report.Checks, 1)
+	assert.Equal(t, model.PREFLIGHT_STATUS_PASS, report.Checks[0].Status)
+
+	// A directory can't be made under a file
+	file := filepath.Join(dir, "file")
+	require.NoError(t, ioutil.WriteFile(file, []byte("not a directory"), 0600))
+
+	report = &model.PreflightReport{}
+	checkPreflightDirectory(report, PREFLIGHT_CHECK_PLUGIN_DIRECTORY, filepath.Join(file, "plugins"), model.PREFLIGHT_STATUS_WARN)
+	require.Len(t, report.Checks, 1)
+	assert.Equal(t, model.PREFLIGHT_STATUS_WARN, report.Checks[0].Status)
+}
+
+func TestCompareVersions(t *testing.T) {
+	assert.True(t, compareVersions("5.0.0", "4.10.0") > 0)
+	assert.True(t, compareVersions("4.9.0", "4.10.0") < 0)
+	assert.True(t, compareVersions("5.0.1", "5.0.0") > 0)
+	assert.Equal(t, int64(0), compareVersions("5.0.0", "5.0.0"))
+}
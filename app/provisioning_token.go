@@ -0,0 +1,71 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func (a *App) CreateProvisioningToken(token *model.ProvisioningToken) (*model.ProvisioningToken, *model.AppError) {
+	result := <-a.Srv.Store.ProvisioningToken().Save(token)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.ProvisioningToken), nil
+}
+
+func (a *App) GetProvisioningToken(tokenId string) (*model.ProvisioningToken, *model.AppError) {
+	result := <-a.Srv.Store.ProvisioningToken().Get(tokenId)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	token := result.Data.(*model.ProvisioningToken)
+	token.Sanitize()
+
+	return token, nil
+}
+
+func (a *App) GetProvisioningTokens(page int, perPage int) ([]*model.ProvisioningToken, *model.AppError) {
+	result := <-a.Srv.Store.ProvisioningToken().GetAll(page*perPage, perPage)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	tokens := result.Data.([]*model.ProvisioningToken)
+	for _, token := range tokens {
+		token.Sanitize()
+	}
+
+	return tokens, nil
+}
+
+func (a *App) RevokeProvisioningToken(tokenId string) *model.AppError {
+	if result := <-a.Srv.Store.ProvisioningToken().Delete(tokenId); result.Err != nil {
+		return result.Err
+	}
+
+	return nil
+}
+
+// AuthenticateProvisioningToken returns the provisioning token matching the secret sent by an identity provider.
+func (a *App) AuthenticateProvisioningToken(tokenString string) (*model.ProvisioningToken, *model.AppError) {
+	if tokenString == "" {
+		return nil, model.NewAppError("AuthenticateProvisioningToken", "app.provisioning_token.invalid.app_error", nil, "", http.StatusUnauthorized)
+	}
+
+	result := <-a.Srv.Store.ProvisioningToken().GetByToken(tokenString)
+	if result.Err != nil {
+		if result.Err.StatusCode == http.StatusNotFound {
+			return nil, model.NewAppError("AuthenticateProvisioningToken", "app.provisioning_token.invalid.app_error", nil, result.Err.Error(), http.StatusUnauthorized)
+		}
+
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.ProvisioningToken), nil
+}
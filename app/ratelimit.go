@@ -23,9 +23,11 @@ type RateLimiter struct {
 	useAuth              bool
 	useIP                bool
 	header               string
+	trustedProxyIPHeader string
+	trustedProxyCIDRs    string
 }
 
-func NewRateLimiter(settings *model.RateLimitSettings) (*RateLimiter, error) {
+func NewRateLimiter(settings *model.RateLimitSettings, serviceSettings *model.ServiceSettings) (*RateLimiter, error) {
 	store, err := memstore.New(*settings.MemoryStoreSize)
 	if err != nil {
 		return nil, errors.Wrap(err, utils.T("api.server.start_server.rate_limiting_memory_store"))
@@ -46,6 +48,8 @@ func NewRateLimiter(settings *model.RateLimitSettings) (*RateLimiter, error) {
 		useAuth:              *settings.VaryByUser,
 		useIP:                *settings.VaryByRemoteAddr,
 		header:               settings.VaryByHeader,
+		trustedProxyIPHeader: *serviceSettings.TrustedProxyIPHeader,
+		trustedProxyCIDRs:    *serviceSettings.TrustedProxyCIDRs,
 	}, nil
 }
 
@@ -57,14 +61,14 @@ func (rl *RateLimiter) GenerateKey(r *http.Request) string {
 		if tokenLocation != TokenLocationNotFound {
 			key += token
 		} else if rl.useIP { // If we don't find an authentication token and IP based is enabled, fall back to IP
-			key += utils.GetIpAddress(r)
+			key += utils.GetIpAddress(r, rl.trustedProxyIPHeader, rl.trustedProxyCIDRs)
 		}
 	} else if rl.useIP { // Only if Auth based is not enabed do we use a plain IP based
-		key += utils.GetIpAddress(r)
+		key += utils.GetIpAddress(r, rl.trustedProxyIPHeader, rl.trustedProxyCIDRs)
 	}
 
-	// Note that most of the time the user won't have to set this because the utils.GetIpAddress above tries the
-	// most common headers anyway.
+	// Note that most of the time the user won't have to set this because the utils.GetIpAddress above reads the
+	// address passed along by trusted proxies anyway.
 	if rl.header != "" {
 		key += strings.ToLower(r.Header.Get(rl.header))
 	}
@@ -25,9 +25,15 @@ func genRateLimitSettings(useAuth, useIP bool, header string) *model.RateLimitSe
 	}
 }
 
+func genServiceSettings() *model.ServiceSettings {
+	settings := &model.ServiceSettings{}
+	settings.SetDefaults()
+	return settings
+}
+
 func TestNewRateLimiterSuccess(t *testing.T) {
 	settings := genRateLimitSettings(false, false, "")
-	rateLimiter, err := NewRateLimiter(settings)
+	rateLimiter, err := NewRateLimiter(settings, genServiceSettings())
 	require.NotNil(t, rateLimiter)
 	require.NoError(t, err)
 }
@@ -35,7 +41,7 @@ func TestNewRateLimiterSuccess(t *testing.T) {
 func TestNewRateLimiterFailure(t *testing.T) {
 	invalidSettings := genRateLimitSettings(false, false, "")
 	invalidSettings.MaxBurst = model.NewInt(-100)
-	rateLimiter, err := NewRateLimiter(invalidSettings)
+	rateLimiter, err := NewRateLimiter(invalidSettings, genServiceSettings())
 	require.Nil(t, rateLimiter)
 	require.Error(t, err)
 }
@@ -73,10 +79,81 @@ func TestGenerateKey(t *testing.T) {
 			req.Header.Set(tc.header, tc.headerResult)
 		}
 
-		rateLimiter, _ := NewRateLimiter(genRateLimitSettings(tc.useAuth, tc.useIP, tc.header))
+		rateLimiter, _ := NewRateLimiter(genRateLimitSettings(tc.useAuth, tc.useIP, tc.header), genServiceSettings())
 
 		key := rateLimiter.GenerateKey(req)
 
 		require.Equal(t, tc.expectedKey, key, "Wrong key on test "+strconv.Itoa(testnum))
 	}
 }
+
+func TestGenerateKeyBehindProxy(t *testing.T) {
+	rateLimiter, err := NewRateLimiter(genRateLimitSettings(false, true, ""), genServiceSettings())
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "127.0.0.1:80"
+	req.Header.Set(model.HEADER_FORWARDED, "192.0.2.1, 203.0.113.5")
+	require.Equal(t, "203.0.113.5", rateLimiter.GenerateKey(req), "should use the address passed along by a trusted proxy")
+
+	req.RemoteAddr = "198.51.100.7:80"
+	require.Equal(t, "198.51.100.7", rateLimiter.GenerateKey(req), "shouldn't trust the header from anyone else")
+}
+
+func TestGenerateKeyBehindProxyWithRealIp(t *testing.T) {
+	rateLimiter, err := NewRateLimiter(genRateLimitSettings(false, true, ""), genServiceSettings())
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "127.0.0.1:80"
+	req.Header.Set(model.HEADER_REAL_IP, "203.0.113.5")
+	require.Equal(t, "203.0.113.5", rateLimiter.GenerateKey(req), "should fall back to X-Real-IP from a trusted proxy")
+
+	serviceSettings := genServiceSettings()
+	*serviceSettings.TrustedProxyIPHeader = ""
+	rateLimiter, err = NewRateLimiter(genRateLimitSettings(false, true, ""), serviceSettings)
+	require.NoError(t, err)
+	require.Equal(t, "127.0.0.1", rateLimiter.GenerateKey(req), "shouldn't read X-Real-IP while proxies aren't trusted")
+}
+
+func TestSetupRateLimiter(t *testing.T) {
+	a := &App{Srv: &Server{}}
+
+	cfg := &model.Config{}
+	cfg.SetDefaults()
+	*cfg.RateLimitSettings.Enable = false
+	*cfg.RateLimitSettings.VaryByRemoteAddr = true
+
+	handler := a.rateLimitHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	request := func() int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "198.51.100.7:80"
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	require.NoError(t, a.setupRateLimiter(cfg))
+	require.Nil(t, a.Srv.RateLimiter())
+	for i := 0; i < 5; i++ {
+		require.Equal(t, http.StatusOK, request(), "requests shouldn't be limited while rate limiting is disabled")
+	}
+
+	enabled := cfg.Clone()
+	*enabled.RateLimitSettings.Enable = true
+	*enabled.RateLimitSettings.PerSec = 1
+	*enabled.RateLimitSettings.MaxBurst = 1
+	require.True(t, rateLimiterSettingsChanged(cfg, enabled))
+
+	require.NoError(t, a.setupRateLimiter(enabled))
+	require.NotNil(t, a.Srv.RateLimiter())
+	require.Equal(t, http.StatusOK, request())
+	require.Equal(t, http.StatusOK, request())
+	require.Equal(t, http.StatusTooManyRequests, request(), "the new settings should apply to the existing handler")
+
+	require.False(t, rateLimiterSettingsChanged(enabled, enabled.Clone()))
+
+	require.NoError(t, a.setupRateLimiter(cfg))
+	require.Nil(t, a.Srv.RateLimiter())
+	require.Equal(t, http.StatusOK, request())
+}
@@ -4,7 +4,10 @@
 package app
 
 import (
+	"net/http"
+
 	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
 )
 
 func (a *App) SaveReactionForPost(reaction *model.Reaction) (*model.Reaction, *model.AppError) {
@@ -13,17 +16,68 @@ func (a *App) SaveReactionForPost(reaction *model.Reaction) (*model.Reaction, *m
 		return nil, err
 	}
 
-	if result := <-a.Srv.Store.Reaction().Save(reaction); result.Err != nil {
-		return nil, result.Err
+	channel, err := a.GetChannel(post.ChannelId)
+	if err != nil {
+		return nil, err
+	}
+
+	if channel.DisableReactions {
+		return nil, model.NewAppError("SaveReactionForPost", "app.reaction.save_reaction.disabled.app_error", nil, "channel_id="+channel.Id, http.StatusForbidden)
+	}
+
+	if err := a.checkUniqueEmojiReactionLimit(post, reaction); err != nil {
+		return nil, err
+	}
+
+	// The reaction's creation time is included in its event, so it needs to be set before the event is saved with it
+	reaction.PreSave()
+	message, outboxEvent := a.newReactionEvent(model.WEBSOCKET_EVENT_REACTION_ADDED, reaction, post)
+
+	var result store.StoreResult
+	if outboxEvent != nil {
+		result = <-a.Srv.Store.Reaction().SaveWithWebSocketEvent(reaction, outboxEvent)
 	} else {
-		reaction = result.Data.(*model.Reaction)
+		result = <-a.Srv.Store.Reaction().Save(reaction)
+	}
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	reaction = result.Data.(*model.Reaction)
 
-		a.Go(func() {
-			a.sendReactionEvent(model.WEBSOCKET_EVENT_REACTION_ADDED, reaction, post)
-		})
+	a.Go(func() {
+		a.sendReactionEvent(message, outboxEvent, post)
+	})
 
-		return reaction, nil
+	return reaction, nil
+}
+
+// checkUniqueEmojiReactionLimit returns an error if reaction would add a new emoji to a post which already has
+// reactions with as many different emoji as ServiceSettings.UniqueEmojiReactionLimitPerPost allows. Reacting
+// with an emoji that's already on the post is always allowed.
+func (a *App) checkUniqueEmojiReactionLimit(post *model.Post, reaction *model.Reaction) *model.AppError {
+	limit := *a.Config().ServiceSettings.UniqueEmojiReactionLimitPerPost
+	if limit == 0 || !post.HasReactions {
+		return nil
+	}
+
+	result := <-a.Srv.Store.Reaction().GetCountsForPosts([]string{post.Id}, reaction.UserId)
+	if result.Err != nil {
+		return result.Err
 	}
+
+	counts := result.Data.([]*model.ReactionCount)
+	for _, count := range counts {
+		if count.EmojiName == reaction.EmojiName {
+			return nil
+		}
+	}
+
+	if len(counts) >= limit {
+		return model.NewAppError("SaveReactionForPost", "app.reaction.save.unique_emoji_limit.app_error", map[string]interface{}{"Limit": limit}, "post_id="+post.Id, http.StatusBadRequest)
+	}
+
+	return nil
 }
 
 func (a *App) GetReactionsForPost(postId string) ([]*model.Reaction, *model.AppError) {
@@ -40,22 +94,45 @@ func (a *App) DeleteReactionForPost(reaction *model.Reaction) *model.AppError {
 		return err
 	}
 
-	if result := <-a.Srv.Store.Reaction().Delete(reaction); result.Err != nil {
-		return result.Err
+	message, outboxEvent := a.newReactionEvent(model.WEBSOCKET_EVENT_REACTION_REMOVED, reaction, post)
+
+	var result store.StoreResult
+	if outboxEvent != nil {
+		result = <-a.Srv.Store.Reaction().DeleteWithWebSocketEvent(reaction, outboxEvent)
 	} else {
-		a.Go(func() {
-			a.sendReactionEvent(model.WEBSOCKET_EVENT_REACTION_REMOVED, reaction, post)
-		})
+		result = <-a.Srv.Store.Reaction().Delete(reaction)
+	}
+	if result.Err != nil {
+		return result.Err
 	}
 
+	a.Go(func() {
+		a.sendReactionEvent(message, outboxEvent, post)
+	})
+
 	return nil
 }
 
-func (a *App) sendReactionEvent(event string, reaction *model.Reaction, post *model.Post) {
-	// send out that a reaction has been added/removed
+// newReactionEvent returns the websocket event for a reaction being added or removed, along with the outbox event to
+// save with the change if websocket events are meant to be reliable.
+func (a *App) newReactionEvent(event string, reaction *model.Reaction, post *model.Post) (*model.WebSocketEvent, *model.WebSocketOutboxEvent) {
 	message := model.NewWebSocketEvent(event, "", post.ChannelId, "", nil)
 	message.Add("reaction", reaction.ToJson())
-	a.Publish(message)
+
+	if !*a.Config().ServiceSettings.ReliableWebsocketEvents {
+		return message, nil
+	}
+
+	return message, model.NewWebSocketOutboxEvent(message)
+}
+
+func (a *App) sendReactionEvent(message *model.WebSocketEvent, outboxEvent *model.WebSocketOutboxEvent, post *model.Post) {
+	// send out that a reaction has been added/removed
+	if outboxEvent != nil {
+		a.publishWebSocketOutboxEvent(outboxEvent.Id, message)
+	} else {
+		a.Publish(message)
+	}
 
 	// The post is always modified since the UpdateAt always changes
 	a.InvalidateCacheForChannelPosts(post.ChannelId)
@@ -0,0 +1,45 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestSaveReactionUniqueEmojiLimit(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.UniqueEmojiReactionLimitPerPost = 3 })
+
+	post := th.CreatePost(th.BasicChannel)
+
+	for _, emojiName := range []string{"smile", "sad", "angry"} {
+		_, err := th.App.SaveReactionForPost(&model.Reaction{UserId: th.BasicUser.Id, PostId: post.Id, EmojiName: emojiName})
+		require.Nil(t, err)
+	}
+
+	_, err := th.App.SaveReactionForPost(&model.Reaction{UserId: th.BasicUser.Id, PostId: post.Id, EmojiName: "grinning"})
+	require.NotNil(t, err)
+	assert.Equal(t, "app.reaction.save.unique_emoji_limit.app_error", err.Id)
+	assert.Equal(t, http.StatusBadRequest, err.StatusCode)
+
+	_, err = th.App.SaveReactionForPost(&model.Reaction{UserId: th.BasicUser2.Id, PostId: post.Id, EmojiName: "angry"})
+	assert.Nil(t, err, "should be able to react with an emoji already on the post")
+
+	// removing an emoji makes room for another one
+	require.Nil(t, th.App.DeleteReactionForPost(&model.Reaction{UserId: th.BasicUser.Id, PostId: post.Id, EmojiName: "smile"}))
+	_, err = th.App.SaveReactionForPost(&model.Reaction{UserId: th.BasicUser.Id, PostId: post.Id, EmojiName: "grinning"})
+	assert.Nil(t, err)
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.UniqueEmojiReactionLimitPerPost = 0 })
+	_, err = th.App.SaveReactionForPost(&model.Reaction{UserId: th.BasicUser.Id, PostId: post.Id, EmojiName: "smile"})
+	assert.Nil(t, err, "0 should disable the limit")
+}
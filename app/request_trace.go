@@ -0,0 +1,71 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"math/rand"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// REQUEST_TRACE_RECENT_MAX is how many of the most recent slow request traces are kept.
+const REQUEST_TRACE_RECENT_MAX = 100
+
+// ShouldTraceRequest returns true if a request that's about to be handled should be traced, picking requests at the
+// configured sample rate while request tracing is enabled.
+func (a *App) ShouldTraceRequest() bool {
+	settings := &a.Config().ServiceSettings
+	if !*settings.EnableRequestTracing {
+		return false
+	}
+
+	return rand.Float64() < *settings.RequestTracingSampleRate
+}
+
+// RecordRequestTrace logs a summary of a finished request trace and keeps it to be looked at later if the request was
+// slower than the configured threshold.
+func (a *App) RecordRequestTrace(trace *model.RequestTrace) {
+	counts, durations := trace.SpanTotals()
+
+	a.Log.Info("Traced request",
+		mlog.String("request_id", trace.RequestId),
+		mlog.String("handler", trace.Handler),
+		mlog.String("user_id", trace.UserId),
+		mlog.Float64("duration_millis", trace.DurationMillis),
+		mlog.Int("store_calls", counts[model.REQUEST_TRACE_SPAN_STORE]),
+		mlog.Float64("store_millis", durations[model.REQUEST_TRACE_SPAN_STORE]),
+		mlog.Int("filestore_calls", counts[model.REQUEST_TRACE_SPAN_FILESTORE]),
+		mlog.Float64("filestore_millis", durations[model.REQUEST_TRACE_SPAN_FILESTORE]),
+		mlog.Int("plugin_hook_calls", counts[model.REQUEST_TRACE_SPAN_PLUGIN_HOOK]),
+		mlog.Float64("plugin_hook_millis", durations[model.REQUEST_TRACE_SPAN_PLUGIN_HOOK]),
+		mlog.Int("dropped_spans", trace.DroppedSpans),
+	)
+
+	if trace.DurationMillis < float64(*a.Config().ServiceSettings.RequestTracingSlowThresholdMilliseconds) {
+		return
+	}
+
+	a.recentRequestTracesLock.Lock()
+	defer a.recentRequestTracesLock.Unlock()
+
+	a.recentRequestTraces = append(a.recentRequestTraces, trace)
+	if len(a.recentRequestTraces) > REQUEST_TRACE_RECENT_MAX {
+		a.recentRequestTraces = a.recentRequestTraces[len(a.recentRequestTraces)-REQUEST_TRACE_RECENT_MAX:]
+	}
+}
+
+// GetRecentRequestTraces returns the traces of the most recent slow requests that were handled by this server, from
+// newest to oldest.
+func (a *App) GetRecentRequestTraces() []*model.RequestTrace {
+	a.recentRequestTracesLock.Lock()
+	defer a.recentRequestTracesLock.Unlock()
+
+	traces := make([]*model.RequestTrace, 0, len(a.recentRequestTraces))
+	for i := len(a.recentRequestTraces) - 1; i >= 0; i-- {
+		traces = append(traces, a.recentRequestTraces[i])
+	}
+
+	return traces
+}
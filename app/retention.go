@@ -0,0 +1,228 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func (a *App) CreateRetentionPolicy(policy *model.RetentionPolicy) (*model.RetentionPolicy, *model.AppError) {
+	policy.Id = ""
+
+	result := <-a.Srv.Store.RetentionPolicy().Save(policy)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.RetentionPolicy), nil
+}
+
+func (a *App) GetRetentionPolicy(policyId string) (*model.RetentionPolicy, *model.AppError) {
+	result := <-a.Srv.Store.RetentionPolicy().Get(policyId)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.RetentionPolicy), nil
+}
+
+func (a *App) GetRetentionPolicies() ([]*model.RetentionPolicy, *model.AppError) {
+	result := <-a.Srv.Store.RetentionPolicy().GetAll()
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.([]*model.RetentionPolicy), nil
+}
+
+func (a *App) UpdateRetentionPolicy(policy *model.RetentionPolicy) (*model.RetentionPolicy, *model.AppError) {
+	result := <-a.Srv.Store.RetentionPolicy().Update(policy)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.RetentionPolicy), nil
+}
+
+func (a *App) DeleteRetentionPolicy(policyId string) *model.AppError {
+	if _, err := a.GetRetentionPolicy(policyId); err != nil {
+		return err
+	}
+
+	return (<-a.Srv.Store.RetentionPolicy().Delete(policyId)).Err
+}
+
+func (a *App) AddChannelsToRetentionPolicy(policyId string, channelIds []string) *model.AppError {
+	if _, err := a.GetRetentionPolicy(policyId); err != nil {
+		return err
+	}
+
+	return (<-a.Srv.Store.RetentionPolicy().AddChannels(policyId, channelIds)).Err
+}
+
+func (a *App) RemoveChannelsFromRetentionPolicy(policyId string, channelIds []string) *model.AppError {
+	return (<-a.Srv.Store.RetentionPolicy().RemoveChannels(policyId, channelIds)).Err
+}
+
+func (a *App) AddTeamsToRetentionPolicy(policyId string, teamIds []string) *model.AppError {
+	if _, err := a.GetRetentionPolicy(policyId); err != nil {
+		return err
+	}
+
+	return (<-a.Srv.Store.RetentionPolicy().AddTeams(policyId, teamIds)).Err
+}
+
+func (a *App) RemoveTeamsFromRetentionPolicy(policyId string, teamIds []string) *model.AppError {
+	return (<-a.Srv.Store.RetentionPolicy().RemoveTeams(policyId, teamIds)).Err
+}
+
+type retentionScope struct {
+	policyId      string
+	messageCutoff int64
+	fileCutoff    int64
+}
+
+func (a *App) getRetentionScopes(now int64) ([]retentionScope, *model.AppError) {
+	settings := a.Config().BasicRetentionSettings
+
+	scopes := []retentionScope{{
+		policyId:      "",
+		messageCutoff: model.RetentionCutoff(now, *settings.MessageRetentionDays),
+		fileCutoff:    model.RetentionCutoff(now, *settings.FileRetentionDays),
+	}}
+
+	policies, err := a.GetRetentionPolicies()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, policy := range policies {
+		scopes = append(scopes, retentionScope{
+			policyId:      policy.Id,
+			messageCutoff: model.RetentionCutoff(now, policy.MessageRetentionDays),
+			fileCutoff:    model.RetentionCutoff(now, policy.FileRetentionDays),
+		})
+	}
+
+	return scopes, nil
+}
+
+// RunBasicRetention permanently deletes the posts and files that are older than the retention period of
+// the channel they belong to. If dryRun is set, nothing is deleted and the result holds what would have
+// been. The optional progress callback is called after each batch with the number of items deleted so far
+// out of the total, and stops the run early by returning false.
+func (a *App) RunBasicRetention(dryRun bool, progress func(done int64, total int64) bool) (*model.RetentionRunResult, *model.AppError) {
+	settings := a.Config().BasicRetentionSettings
+	exemptPinned := *settings.ExemptPinnedPosts
+	batchSize := int64(*settings.BatchSize)
+
+	scopes, err := a.getRetentionScopes(model.GetMillis())
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.RetentionRunResult{DryRun: dryRun}
+
+	for _, scope := range scopes {
+		if scope.messageCutoff > 0 {
+			countResult := <-a.Srv.Store.RetentionPolicy().CountPostsForDeletion(scope.policyId, scope.messageCutoff, exemptPinned)
+			if countResult.Err != nil {
+				return nil, countResult.Err
+			}
+			result.PostsDeleted += countResult.Data.(int64)
+		}
+
+		if scope.fileCutoff > 0 {
+			countResult := <-a.Srv.Store.RetentionPolicy().CountFilesForDeletion(scope.policyId, scope.fileCutoff, exemptPinned)
+			if countResult.Err != nil {
+				return nil, countResult.Err
+			}
+			result.FilesDeleted += countResult.Data.(int64)
+		}
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	total := result.PostsDeleted + result.FilesDeleted
+	result.PostsDeleted = 0
+	result.FilesDeleted = 0
+
+	defer func() {
+		if result.PostsDeleted > 0 || result.FilesDeleted > 0 {
+			a.Srv.Store.Post().ClearCaches()
+			a.Srv.Store.FileInfo().ClearCaches()
+		}
+	}()
+
+	reportProgress := func() bool {
+		if progress == nil {
+			return true
+		}
+		return progress(result.PostsDeleted+result.FilesDeleted, total)
+	}
+
+	for _, scope := range scopes {
+		for scope.messageCutoff > 0 {
+			batchResult := <-a.Srv.Store.RetentionPolicy().PermanentDeletePostsBatch(scope.policyId, scope.messageCutoff, exemptPinned, batchSize)
+			if batchResult.Err != nil {
+				return nil, batchResult.Err
+			}
+
+			posts := batchResult.Data.([]*model.Post)
+			if len(posts) == 0 {
+				break
+			}
+
+			a.removeRetainedPostsFromUnreads(posts)
+
+			result.PostsDeleted += int64(len(posts))
+			if !reportProgress() {
+				return result, nil
+			}
+		}
+
+		for scope.fileCutoff > 0 {
+			batchResult := <-a.Srv.Store.RetentionPolicy().PermanentDeleteFilesBatch(scope.policyId, scope.fileCutoff, exemptPinned, batchSize)
+			if batchResult.Err != nil {
+				return nil, batchResult.Err
+			}
+
+			deleted := batchResult.Data.(int64)
+			if deleted == 0 {
+				break
+			}
+
+			result.FilesDeleted += deleted
+			if !reportProgress() {
+				return result, nil
+			}
+		}
+	}
+
+	mlog.Info("Basic retention run deleted posts and files", mlog.Int64("posts", result.PostsDeleted), mlog.Int64("files", result.FilesDeleted))
+
+	return result, nil
+}
+
+// removeRetainedPostsFromUnreads takes the posts deleted by a retention run out of the unread counts of their
+// channels. Posts that had already been deleted were taken out of them at the time.
+func (a *App) removeRetainedPostsFromUnreads(posts []*model.Post) {
+	byChannel := make(map[string][]*model.Post)
+	for _, post := range posts {
+		if post.DeleteAt == 0 {
+			byChannel[post.ChannelId] = append(byChannel[post.ChannelId], post)
+		}
+	}
+
+	for channelId, channelPosts := range byChannel {
+		if err := a.removeDeletedPostsFromUnreads(channelId, channelPosts); err != nil {
+			mlog.Warn(fmt.Sprintf("Failed to update unread counts for posts deleted by retention, channel_id=%v, err=%v", channelId, err), mlog.String("channel_id", channelId))
+		}
+	}
+}
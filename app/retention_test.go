@@ -0,0 +1,138 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func (me *TestHelper) createPostAt(channel *model.Channel, createAt int64) *model.Post {
+	post, err := me.App.CreatePost(&model.Post{
+		UserId:    me.BasicUser.Id,
+		ChannelId: channel.Id,
+		Message:   "message " + model.NewId(),
+		CreateAt:  createAt,
+	}, channel, false)
+	if err != nil {
+		panic(err)
+	}
+
+	return post
+}
+
+func TestRunBasicRetention(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	day := int64(24 * 60 * 60 * 1000)
+	now := model.GetMillis()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.BasicRetentionSettings.MessageRetentionDays = 365
+		*cfg.BasicRetentionSettings.FileRetentionDays = 0
+	})
+
+	shortLived, err := th.App.CreateRetentionPolicy(&model.RetentionPolicy{
+		DisplayName:          "Short lived",
+		MessageRetentionDays: 7,
+	})
+	require.Nil(t, err)
+	defer th.App.DeleteRetentionPolicy(shortLived.Id)
+
+	forever, err := th.App.CreateRetentionPolicy(&model.RetentionPolicy{
+		DisplayName: "Forever",
+	})
+	require.Nil(t, err)
+	defer th.App.DeleteRetentionPolicy(forever.Id)
+
+	shortChannel := th.CreateChannel(th.BasicTeam)
+	foreverChannel := th.CreateChannel(th.BasicTeam)
+	require.Nil(t, th.App.AddChannelsToRetentionPolicy(shortLived.Id, []string{shortChannel.Id}))
+	require.Nil(t, th.App.AddChannelsToRetentionPolicy(forever.Id, []string{foreverChannel.Id}))
+
+	globalOld := th.createPostAt(th.BasicChannel, now-400*day)
+	globalRecent := th.createPostAt(th.BasicChannel, now-30*day)
+	shortOld := th.createPostAt(shortChannel, now-30*day)
+	shortRecent := th.createPostAt(shortChannel, now-day)
+	foreverOld := th.createPostAt(foreverChannel, now-400*day)
+
+	result, err := th.App.RunBasicRetention(true, nil)
+	require.Nil(t, err)
+	assert.True(t, result.DryRun)
+	assert.True(t, result.PostsDeleted >= 2)
+
+	_, err = th.App.GetSinglePost(globalOld.Id)
+	assert.Nil(t, err, "a dry run shouldn't delete anything")
+
+	var calls int
+	result, err = th.App.RunBasicRetention(false, func(done int64, total int64) bool {
+		calls++
+		assert.True(t, done <= total)
+		return true
+	})
+	require.Nil(t, err)
+	assert.False(t, result.DryRun)
+	assert.True(t, result.PostsDeleted >= 2)
+	assert.True(t, calls > 0)
+
+	for _, post := range []*model.Post{globalOld, shortOld} {
+		_, err = th.App.GetSinglePost(post.Id)
+		assert.NotNil(t, err)
+	}
+
+	for _, post := range []*model.Post{globalRecent, shortRecent, foreverOld} {
+		_, err = th.App.GetSinglePost(post.Id)
+		assert.Nil(t, err)
+	}
+
+	t.Run("stops when progress returns false", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.BasicRetentionSettings.BatchSize = 1 })
+
+		first := th.createPostAt(shortChannel, now-30*day)
+		second := th.createPostAt(shortChannel, now-30*day)
+
+		result, err := th.App.RunBasicRetention(false, func(done int64, total int64) bool {
+			return false
+		})
+		require.Nil(t, err)
+		assert.Equal(t, int64(1), result.PostsDeleted)
+
+		_, firstErr := th.App.GetSinglePost(first.Id)
+		_, secondErr := th.App.GetSinglePost(second.Id)
+		assert.True(t, (firstErr == nil) != (secondErr == nil))
+	})
+}
+
+func TestRetentionPolicyAssignments(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	err := th.App.AddChannelsToRetentionPolicy(model.NewId(), []string{th.BasicChannel.Id})
+	require.NotNil(t, err)
+	assert.Equal(t, "store.sql_retention_policy.get.missing.app_error", err.Id)
+
+	policy, err := th.App.CreateRetentionPolicy(&model.RetentionPolicy{
+		DisplayName:          "Team policy",
+		MessageRetentionDays: 90,
+	})
+	require.Nil(t, err)
+	defer th.App.DeleteRetentionPolicy(policy.Id)
+
+	require.Nil(t, th.App.AddTeamsToRetentionPolicy(policy.Id, []string{th.BasicTeam.Id}))
+
+	policy, err = th.App.GetRetentionPolicy(policy.Id)
+	require.Nil(t, err)
+	assert.Equal(t, []string{th.BasicTeam.Id}, policy.TeamIds)
+
+	require.Nil(t, th.App.RemoveTeamsFromRetentionPolicy(policy.Id, []string{th.BasicTeam.Id}))
+
+	policy, err = th.App.GetRetentionPolicy(policy.Id)
+	require.Nil(t, err)
+	assert.Len(t, policy.TeamIds, 0)
+}
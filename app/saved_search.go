@@ -0,0 +1,88 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// CreateSavedSearch saves a search for userId. The names of a user's saved searches must be unique, ignoring
+// case, and a user can have at most model.SAVED_SEARCH_MAX_PER_USER of them.
+func (a *App) CreateSavedSearch(userId string, search *model.SavedSearch) (*model.SavedSearch, *model.AppError) {
+	search.Id = ""
+	search.CreateAt = 0
+	search.PreSave()
+	if err := search.IsValid(); err != nil {
+		return nil, err
+	}
+
+	existing, err := a.GetSavedSearches(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(existing) >= model.SAVED_SEARCH_MAX_PER_USER {
+		return nil, model.NewAppError("CreateSavedSearch", "app.saved_search.create.too_many.app_error", map[string]interface{}{"Max": model.SAVED_SEARCH_MAX_PER_USER}, "user_id="+userId, http.StatusBadRequest)
+	}
+
+	for _, other := range existing {
+		if strings.EqualFold(other.Name, search.Name) {
+			return nil, model.NewAppError("CreateSavedSearch", "app.saved_search.create.duplicate_name.app_error", map[string]interface{}{"Name": search.Name}, "user_id="+userId, http.StatusBadRequest)
+		}
+	}
+
+	preference := search.ToPreference(userId)
+	if err := preference.IsValid(); err != nil {
+		return nil, model.NewAppError("CreateSavedSearch", "app.saved_search.create.too_long.app_error", nil, err.Error(), http.StatusBadRequest)
+	}
+
+	preferences := model.Preferences{*preference}
+	if result := <-a.Srv.Store.Preference().Save(&preferences); result.Err != nil {
+		return nil, result.Err
+	}
+
+	a.PublishPreferencesEvent(model.WEBSOCKET_EVENT_PREFERENCES_CHANGED, userId, preferences)
+
+	return search, nil
+}
+
+// GetSavedSearches returns the searches that userId has saved, oldest first.
+func (a *App) GetSavedSearches(userId string) ([]*model.SavedSearch, *model.AppError) {
+	result := <-a.Srv.Store.Preference().GetCategory(userId, model.PREFERENCE_CATEGORY_SAVED_SEARCH)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	searches := []*model.SavedSearch{}
+	for _, preference := range result.Data.(model.Preferences) {
+		if search := model.SavedSearchFromPreference(&preference); search != nil {
+			searches = append(searches, search)
+		}
+	}
+
+	sort.Slice(searches, func(i, j int) bool {
+		return searches[i].CreateAt < searches[j].CreateAt
+	})
+
+	return searches, nil
+}
+
+func (a *App) DeleteSavedSearch(userId string, savedSearchId string) *model.AppError {
+	if result := <-a.Srv.Store.Preference().Get(userId, model.PREFERENCE_CATEGORY_SAVED_SEARCH, savedSearchId); result.Err != nil {
+		return model.NewAppError("DeleteSavedSearch", "app.saved_search.get.not_found.app_error", nil, result.Err.Error(), http.StatusNotFound)
+	}
+
+	if result := <-a.Srv.Store.Preference().Delete(userId, model.PREFERENCE_CATEGORY_SAVED_SEARCH, savedSearchId); result.Err != nil {
+		return result.Err
+	}
+
+	preferences := model.Preferences{{UserId: userId, Category: model.PREFERENCE_CATEGORY_SAVED_SEARCH, Name: savedSearchId}}
+	a.PublishPreferencesEvent(model.WEBSOCKET_EVENT_PREFERENCES_DELETED, userId, preferences)
+
+	return nil
+}
@@ -0,0 +1,727 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const (
+	SCIM_PAGE_SIZE = 200
+)
+
+// scimUniquenessErrors are the store errors caused by a user conflicting with an existing one, which SCIM reports
+// as a conflict so that identity providers can link the existing user instead.
+var scimUniquenessErrors = map[string]bool{
+	"store.sql_user.save.email_exists.app_error":             true,
+	"store.sql_user.save.username_exists.app_error":          true,
+	"store.sql_user.update.email_taken.app_error":            true,
+	"store.sql_user.update.username_taken.app_error":         true,
+	"store.sql_user.update_auth_data.email_exists.app_error": true,
+}
+
+func scimConflict(err *model.AppError) *model.AppError {
+	if scimUniquenessErrors[err.Id] {
+		err.StatusCode = http.StatusConflict
+	}
+
+	return err
+}
+
+func (a *App) scimLocation(resource string, id string) string {
+	return a.GetSiteURL() + model.SCIM_URL_SUFFIX + "/" + resource + "/" + id
+}
+
+// isScimUser returns whether the user is managed by the identity provider, which is only the case for the users who
+// sign in with the SCIM auth service. Everyone else, such as bots and users who sign in with a password, is treated
+// as if they don't exist.
+func (a *App) isScimUser(user *model.User) bool {
+	return !user.IsBot && user.AuthService == *a.Config().ScimSettings.UserAuthService
+}
+
+// getScimUser returns the user with the given id if they're managed by the identity provider.
+func (a *App) getScimUser(userId string) (*model.User, *model.AppError) {
+	result := <-a.Srv.Store.User().Get(userId)
+	if result.Err != nil || !a.isScimUser(result.Data.(*model.User)) {
+		return nil, model.NewAppError("getScimUser", "app.scim.user.not_found.app_error", nil, "user_id="+userId, http.StatusNotFound)
+	}
+
+	return result.Data.(*model.User), nil
+}
+
+// getScimUserForUpdate is like getScimUser, except that system admins are refused so that the provisioning token
+// can't be used to take over or lock out their accounts.
+func (a *App) getScimUserForUpdate(userId string) (*model.User, *model.AppError) {
+	user, err := a.getScimUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.RolesGrantPermission(user.GetRoles(), model.PERMISSION_MANAGE_SYSTEM.Id) {
+		return nil, model.NewAppError("getScimUserForUpdate", "app.scim.user.system_admin.app_error", nil, "user_id="+userId, http.StatusForbidden)
+	}
+
+	return user, nil
+}
+
+func (a *App) userToScimUser(user *model.User) *model.ScimUser {
+	userName := user.Username
+	externalId := ""
+	if user.AuthService == *a.Config().ScimSettings.UserAuthService && user.AuthData != nil && *user.AuthData != "" {
+		userName = *user.AuthData
+		externalId = *user.AuthData
+	}
+
+	active := user.DeleteAt == 0
+
+	return &model.ScimUser{
+		Schemas:    []string{model.SCIM_SCHEMA_USER},
+		Id:         user.Id,
+		ExternalId: externalId,
+		UserName:   userName,
+		Name: &model.ScimName{
+			Formatted:  user.GetFullName(),
+			GivenName:  user.FirstName,
+			FamilyName: user.LastName,
+		},
+		NickName: user.Nickname,
+		Title:    user.Position,
+		Emails: []model.ScimEmail{
+			{Value: user.Email, Type: model.SCIM_EMAIL_TYPE_WORK, Primary: true},
+		},
+		Active: &active,
+		Meta:   model.NewScimMeta(model.SCIM_RESOURCE_TYPE_USER, user.CreateAt, user.UpdateAt, a.scimLocation("Users", user.Id)),
+	}
+}
+
+// applyScimUser copies the SCIM attributes onto the user. Attributes that are missing are cleared since SCIM
+// replaces the whole resource.
+func applyScimUser(user *model.User, scimUser *model.ScimUser) *model.AppError {
+	email := scimUser.PrimaryEmail()
+	if email == "" && strings.Contains(scimUser.UserName, "@") {
+		email = scimUser.UserName
+	}
+
+	if email == "" {
+		return model.NewAppError("applyScimUser", "app.scim.user.email_required.app_error", nil, "user_name="+scimUser.UserName, http.StatusBadRequest)
+	}
+
+	user.Email = email
+	user.FirstName = ""
+	user.LastName = ""
+	if scimUser.Name != nil {
+		user.FirstName = scimUser.Name.GivenName
+		user.LastName = scimUser.Name.FamilyName
+	}
+	user.Nickname = scimUser.NickName
+	user.Position = scimUser.Title
+
+	return nil
+}
+
+// scimUsername picks an available username based on the SCIM userName, which is often an email address.
+func (a *App) scimUsername(userName string) string {
+	if i := strings.Index(userName, "@"); i > 0 {
+		userName = userName[:i]
+	}
+
+	base := model.CleanUsername(userName)
+	if len(base) > model.USER_NAME_MAX_LENGTH-4 {
+		base = base[:model.USER_NAME_MAX_LENGTH-4]
+	}
+
+	username := base
+	for i := 1; a.IsUsernameTaken(username); i++ {
+		username = fmt.Sprintf("%v-%v", base, i)
+	}
+
+	return username
+}
+
+// CreateScimUser provisions a user who signs in with the configured SCIM auth service. Provisioning a user who has
+// been deactivated reactivates and updates them, so identity providers can safely provision the same user again.
+func (a *App) CreateScimUser(scimUser *model.ScimUser) (*model.ScimUser, *model.AppError) {
+	if scimUser.UserName == "" {
+		return nil, model.NewAppError("CreateScimUser", "app.scim.user.user_name_required.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	authService := *a.Config().ScimSettings.UserAuthService
+	authData := scimUser.UserName
+
+	if result := <-a.Srv.Store.User().GetByAuth(&authData, authService); result.Err == nil {
+		existing := result.Data.(*model.User)
+		if existing.DeleteAt == 0 {
+			return nil, model.NewAppError("CreateScimUser", "app.scim.user.exists.app_error", nil, "user_id="+existing.Id, http.StatusConflict)
+		}
+
+		if scimUser.Active == nil {
+			scimUser.Active = model.NewBool(true)
+		}
+
+		return a.ReplaceScimUser(existing.Id, scimUser)
+	}
+
+	user := &model.User{
+		AuthService:   authService,
+		AuthData:      &authData,
+		EmailVerified: true,
+	}
+	if err := applyScimUser(user, scimUser); err != nil {
+		return nil, err
+	}
+	user.Username = a.scimUsername(scimUser.UserName)
+
+	ruser, err := a.CreateUser(user)
+	if err != nil {
+		return nil, scimConflict(err)
+	}
+
+	if scimUser.Active != nil && !*scimUser.Active {
+		if _, err := a.UpdateActive(ruser, false); err != nil {
+			return nil, err
+		}
+	}
+
+	return a.GetScimUser(ruser.Id)
+}
+
+func (a *App) GetScimUser(userId string) (*model.ScimUser, *model.AppError) {
+	user, err := a.getScimUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.userToScimUser(user), nil
+}
+
+// SearchScimUsers returns a page of users, starting from the 1-based startIndex, optionally filtered by their id,
+// userName, externalId or email.
+func (a *App) SearchScimUsers(filter *model.ScimFilter, startIndex int, count int) (*model.ScimListResponse, *model.AppError) {
+	authService := *a.Config().ScimSettings.UserAuthService
+	authData := ""
+
+	if filter != nil {
+		var user *model.User
+		switch strings.ToLower(filter.Attribute) {
+		case "id":
+			user, _ = a.getScimUser(filter.Value)
+		case "username", "externalid":
+			// the identity provider's identifier for the user is their auth data, which is filtered by the query below
+			if filter.Value == "" {
+				return model.NewScimListResponse([]interface{}{}, 0, startIndex), nil
+			}
+			authData = filter.Value
+		case "emails", "emails.value":
+			if result := <-a.Srv.Store.User().GetByEmail(filter.Value); result.Err == nil {
+				user = result.Data.(*model.User)
+			}
+		default:
+			return nil, model.NewAppError("SearchScimUsers", "app.scim.filter.unsupported_attribute.app_error", nil, "attribute="+filter.Attribute, http.StatusBadRequest)
+		}
+
+		if authData == "" {
+			resources := []interface{}{}
+			total := 0
+			if user != nil && a.isScimUser(user) {
+				total = 1
+				if startIndex <= 1 && count > 0 {
+					resources = append(resources, a.userToScimUser(user))
+				}
+			}
+
+			return model.NewScimListResponse(resources, total, startIndex), nil
+		}
+	}
+
+	countChan := a.Srv.Store.User().CountUsingAuthService(authService, authData)
+
+	resources := []interface{}{}
+	if count > 0 {
+		result := <-a.Srv.Store.User().GetUsingAuthService(authService, authData, startIndex-1, count)
+		if result.Err != nil {
+			return nil, result.Err
+		}
+
+		for _, user := range result.Data.([]*model.User) {
+			resources = append(resources, a.userToScimUser(user))
+		}
+	}
+
+	result := <-countChan
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return model.NewScimListResponse(resources, int(result.Data.(int64)), startIndex), nil
+}
+
+// ReplaceScimUser updates all of the user's attributes from SCIM, activating or deactivating them as needed.
+func (a *App) ReplaceScimUser(userId string, scimUser *model.ScimUser) (*model.ScimUser, *model.AppError) {
+	user, err := a.getScimUserForUpdate(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	oldEmail := user.Email
+	if err := applyScimUser(user, scimUser); err != nil {
+		return nil, err
+	}
+
+	ruser, err := a.UpdateUser(user, false)
+	if err != nil {
+		return nil, scimConflict(err)
+	}
+
+	if ruser.Email != oldEmail {
+		// the identity provider vouches for the new email
+		if err := a.VerifyUserEmail(ruser.Id); err != nil {
+			return nil, err
+		}
+	}
+
+	authService := *a.Config().ScimSettings.UserAuthService
+	if scimUser.UserName != "" && a.userToScimUser(ruser).UserName != scimUser.UserName {
+		authData := scimUser.UserName
+		if result := <-a.Srv.Store.User().UpdateAuthData(ruser.Id, authService, &authData, "", false); result.Err != nil {
+			return nil, scimConflict(result.Err)
+		}
+	}
+
+	if scimUser.Active != nil && *scimUser.Active != (ruser.DeleteAt == 0) {
+		if _, err := a.UpdateActive(ruser, *scimUser.Active); err != nil {
+			return nil, err
+		}
+	}
+
+	a.InvalidateCacheForUser(ruser.Id)
+
+	return a.GetScimUser(ruser.Id)
+}
+
+func (a *App) PatchScimUser(userId string, patch *model.ScimPatchRequest) (*model.ScimUser, *model.AppError) {
+	user, err := a.getScimUserForUpdate(userId)
+	if err != nil {
+		return nil, err
+	}
+	scimUser := a.userToScimUser(user)
+
+	patched, err := patch.ApplyToUser(scimUser)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.ReplaceScimUser(userId, patched)
+}
+
+// DeactivateScimUser handles a SCIM delete by deactivating the user, since deleting them would also delete
+// everything that they've posted.
+func (a *App) DeactivateScimUser(userId string) *model.AppError {
+	user, err := a.getScimUserForUpdate(userId)
+	if err != nil {
+		return err
+	}
+
+	if user.DeleteAt == 0 {
+		if _, err := a.UpdateActive(user, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *App) scimGroupsAreTeams() bool {
+	return *a.Config().ScimSettings.GroupMapping == model.SCIM_GROUP_MAPPING_TEAMS
+}
+
+func (a *App) newScimGroup(id string, displayName string, createAt int64, updateAt int64, memberIds []string) *model.ScimGroup {
+	members := make([]model.ScimMember, 0, len(memberIds))
+	for _, memberId := range memberIds {
+		members = append(members, model.ScimMember{Value: memberId, Ref: a.scimLocation("Users", memberId)})
+	}
+
+	return &model.ScimGroup{
+		Schemas:     []string{model.SCIM_SCHEMA_GROUP},
+		Id:          id,
+		DisplayName: displayName,
+		Members:     members,
+		Meta:        model.NewScimMeta(model.SCIM_RESOURCE_TYPE_GROUP, createAt, updateAt, a.scimLocation("Groups", id)),
+	}
+}
+
+func (a *App) customGroupToScimGroup(group *model.CustomGroup) (*model.ScimGroup, *model.AppError) {
+	members, err := a.GetCustomGroupMembers(group.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	memberIds := make([]string, 0, len(members))
+	for _, member := range members {
+		memberIds = append(memberIds, member.UserId)
+	}
+
+	return a.newScimGroup(group.Id, group.DisplayName, group.CreateAt, group.UpdateAt, memberIds), nil
+}
+
+func (a *App) teamToScimGroup(team *model.Team) (*model.ScimGroup, *model.AppError) {
+	memberIds, err := a.getScimTeamMemberIds(team.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.newScimGroup(team.Id, team.DisplayName, team.CreateAt, team.UpdateAt, memberIds), nil
+}
+
+// getScimTeamMemberIds returns the members of the team who are managed by the identity provider. Everyone else on
+// the team is left out of the group so that the identity provider never removes them.
+func (a *App) getScimTeamMemberIds(teamId string) ([]string, *model.AppError) {
+	memberIds := []string{}
+
+	for offset := 0; ; offset += SCIM_PAGE_SIZE {
+		members, err := a.GetTeamMembers(teamId, offset, SCIM_PAGE_SIZE)
+		if err != nil {
+			return nil, err
+		}
+
+		userIds := make([]string, 0, len(members))
+		for _, member := range members {
+			userIds = append(userIds, member.UserId)
+		}
+
+		if len(userIds) > 0 {
+			result := <-a.Srv.Store.User().GetProfileByIds(userIds, false)
+			if result.Err != nil {
+				return nil, result.Err
+			}
+
+			for _, user := range result.Data.([]*model.User) {
+				if a.isScimUser(user) {
+					memberIds = append(memberIds, user.Id)
+				}
+			}
+		}
+
+		if len(members) < SCIM_PAGE_SIZE {
+			return memberIds, nil
+		}
+	}
+}
+
+func (a *App) getAllCustomGroups() ([]*model.CustomGroup, *model.AppError) {
+	groups := []*model.CustomGroup{}
+
+	for page := 0; ; page++ {
+		pageGroups, err := a.GetCustomGroups(page, SCIM_PAGE_SIZE)
+		if err != nil {
+			return nil, err
+		}
+
+		groups = append(groups, pageGroups...)
+
+		if len(pageGroups) < SCIM_PAGE_SIZE {
+			return groups, nil
+		}
+	}
+}
+
+func (a *App) getCustomGroupByDisplayName(displayName string) (*model.CustomGroup, *model.AppError) {
+	groups, err := a.getAllCustomGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range groups {
+		if group.DisplayName == displayName {
+			return group, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// scimCustomGroupName picks an available group name based on the SCIM group's display name.
+func (a *App) scimCustomGroupName(displayName string) string {
+	base := model.CleanUsername(displayName)
+	if len(base) > model.USER_NAME_MAX_LENGTH-4 {
+		base = base[:model.USER_NAME_MAX_LENGTH-4]
+	}
+
+	isTaken := func(name string) bool {
+		if result := <-a.Srv.Store.CustomGroup().GetByName(name); result.Err == nil {
+			return true
+		}
+
+		return a.checkCustomGroupNameAvailable(name) != nil
+	}
+
+	name := base
+	for i := 1; isTaken(name); i++ {
+		name = fmt.Sprintf("%v-%v", base, i)
+	}
+
+	return name
+}
+
+// getScimTeam returns the team that a SCIM group is mapped to, which is the team named after the group.
+func (a *App) getScimTeam(displayName string) (*model.Team, *model.AppError) {
+	team, err := a.GetTeamByName(strings.ToLower(displayName))
+	if err != nil {
+		return nil, model.NewAppError("getScimTeam", "app.scim.group.no_team.app_error", map[string]interface{}{"Name": displayName}, err.Error(), http.StatusBadRequest)
+	}
+
+	return team, nil
+}
+
+// CreateScimGroup creates a custom group for the SCIM group or, when groups are mapped to teams, links it to the
+// team with the same name. The members of the group replace the members of the team.
+func (a *App) CreateScimGroup(scimGroup *model.ScimGroup, creatorId string) (*model.ScimGroup, *model.AppError) {
+	if scimGroup.DisplayName == "" {
+		return nil, model.NewAppError("CreateScimGroup", "app.scim.group.display_name_required.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if a.scimGroupsAreTeams() {
+		team, err := a.getScimTeam(scimGroup.DisplayName)
+		if err != nil {
+			return nil, err
+		}
+
+		return a.ReplaceScimGroup(team.Id, scimGroup)
+	}
+
+	if existing, err := a.getCustomGroupByDisplayName(scimGroup.DisplayName); err != nil {
+		return nil, err
+	} else if existing != nil {
+		return nil, model.NewAppError("CreateScimGroup", "app.scim.group.exists.app_error", nil, "group_id="+existing.Id, http.StatusConflict)
+	}
+
+	group, err := a.CreateCustomGroup(&model.CustomGroup{
+		Name:        a.scimCustomGroupName(scimGroup.DisplayName),
+		DisplayName: scimGroup.DisplayName,
+	}, creatorId)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.ReplaceScimGroup(group.Id, scimGroup)
+}
+
+func (a *App) GetScimGroup(groupId string) (*model.ScimGroup, *model.AppError) {
+	if a.scimGroupsAreTeams() {
+		team, err := a.GetTeam(groupId)
+		if err != nil {
+			return nil, model.NewAppError("GetScimGroup", "app.scim.group.not_found.app_error", nil, "group_id="+groupId, http.StatusNotFound)
+		}
+
+		return a.teamToScimGroup(team)
+	}
+
+	group, err := a.GetCustomGroup(groupId)
+	if err != nil {
+		return nil, model.NewAppError("GetScimGroup", "app.scim.group.not_found.app_error", nil, "group_id="+groupId, http.StatusNotFound)
+	}
+
+	return a.customGroupToScimGroup(group)
+}
+
+// SearchScimGroups returns a page of groups, starting from the 1-based startIndex, optionally filtered by their id
+// or display name.
+func (a *App) SearchScimGroups(filter *model.ScimFilter, startIndex int, count int) (*model.ScimListResponse, *model.AppError) {
+	var groupIds []string
+
+	if filter != nil {
+		switch strings.ToLower(filter.Attribute) {
+		case "id":
+			groupIds = []string{filter.Value}
+		case "displayname":
+			if a.scimGroupsAreTeams() {
+				if team, err := a.GetTeamByName(strings.ToLower(filter.Value)); err == nil {
+					groupIds = []string{team.Id}
+				}
+			} else if group, err := a.getCustomGroupByDisplayName(filter.Value); err != nil {
+				return nil, err
+			} else if group != nil {
+				groupIds = []string{group.Id}
+			}
+		default:
+			return nil, model.NewAppError("SearchScimGroups", "app.scim.filter.unsupported_attribute.app_error", nil, "attribute="+filter.Attribute, http.StatusBadRequest)
+		}
+	} else if a.scimGroupsAreTeams() {
+		teams, err := a.GetAllTeams()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, team := range teams {
+			groupIds = append(groupIds, team.Id)
+		}
+	} else {
+		groups, err := a.getAllCustomGroups()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, group := range groups {
+			groupIds = append(groupIds, group.Id)
+		}
+	}
+
+	resources := []interface{}{}
+	total := 0
+	for _, groupId := range groupIds {
+		group, err := a.GetScimGroup(groupId)
+		if err != nil {
+			if err.StatusCode == http.StatusNotFound {
+				continue
+			}
+			return nil, err
+		}
+
+		total++
+		if total >= startIndex && len(resources) < count {
+			resources = append(resources, group)
+		}
+	}
+
+	return model.NewScimListResponse(resources, total, startIndex), nil
+}
+
+// ReplaceScimGroup updates the group's display name and members. The display name of a team isn't changed since
+// it's what the group is mapped by.
+func (a *App) ReplaceScimGroup(groupId string, scimGroup *model.ScimGroup) (*model.ScimGroup, *model.AppError) {
+	current, err := a.GetScimGroup(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	memberIds := scimGroup.MemberIds()
+
+	if a.scimGroupsAreTeams() {
+		if err := a.setScimTeamMembers(groupId, current.MemberIds(), memberIds); err != nil {
+			return nil, err
+		}
+
+		return a.GetScimGroup(groupId)
+	}
+
+	if err := a.setScimCustomGroupMembers(groupId, current.MemberIds(), memberIds); err != nil {
+		return nil, err
+	}
+
+	group, err := a.GetCustomGroup(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	patch := &model.CustomGroupPatch{}
+	if scimGroup.DisplayName != "" {
+		patch.DisplayName = &scimGroup.DisplayName
+	}
+
+	// patching the group even if nothing changed updates its version for the membership changes
+	if _, err := a.PatchCustomGroup(group, patch); err != nil {
+		return nil, err
+	}
+
+	return a.GetScimGroup(groupId)
+}
+
+func (a *App) PatchScimGroup(groupId string, patch *model.ScimPatchRequest) (*model.ScimGroup, *model.AppError) {
+	scimGroup, err := a.GetScimGroup(groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	patched, err := patch.ApplyToGroup(scimGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.ReplaceScimGroup(groupId, patched)
+}
+
+// DeleteScimGroup deletes the custom group for a SCIM group. Teams are left as they are since they're only linked
+// to the group.
+func (a *App) DeleteScimGroup(groupId string) *model.AppError {
+	if _, err := a.GetScimGroup(groupId); err != nil {
+		return err
+	}
+
+	if a.scimGroupsAreTeams() {
+		return nil
+	}
+
+	return a.DeleteCustomGroup(groupId)
+}
+
+func diffScimMembers(currentIds []string, newIds []string) (added []string, removed []string) {
+	current := map[string]bool{}
+	for _, id := range currentIds {
+		current[id] = true
+	}
+
+	wanted := map[string]bool{}
+	for _, id := range newIds {
+		wanted[id] = true
+		if !current[id] {
+			added = append(added, id)
+		}
+	}
+
+	for _, id := range currentIds {
+		if !wanted[id] {
+			removed = append(removed, id)
+		}
+	}
+
+	return added, removed
+}
+
+func (a *App) setScimCustomGroupMembers(groupId string, currentIds []string, memberIds []string) *model.AppError {
+	added, removed := diffScimMembers(currentIds, memberIds)
+
+	if len(added) > 0 {
+		if _, err := a.AddCustomGroupMembers(groupId, added); err != nil {
+			return err
+		}
+	}
+
+	for _, userId := range removed {
+		if err := a.RemoveCustomGroupMember(groupId, userId); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *App) setScimTeamMembers(teamId string, currentIds []string, memberIds []string) *model.AppError {
+	added, removed := diffScimMembers(currentIds, memberIds)
+
+	for _, userId := range added {
+		if _, err := a.getScimUser(userId); err != nil {
+			return model.NewAppError("setScimTeamMembers", "app.scim.group.invalid_member.app_error", nil, "user_id="+userId, http.StatusBadRequest)
+		}
+
+		if _, err := a.AddTeamMember(teamId, userId); err != nil {
+			return err
+		}
+	}
+
+	for _, userId := range removed {
+		// system admins stay on the team even if the identity provider leaves them out of the group
+		if _, err := a.getScimUserForUpdate(userId); err != nil {
+			continue
+		}
+
+		if err := a.RemoveUserFromTeam(teamId, userId, ""); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,370 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func newScimUser(userName string) *model.ScimUser {
+	return &model.ScimUser{
+		Schemas:  []string{model.SCIM_SCHEMA_USER},
+		UserName: userName,
+		Name:     &model.ScimName{GivenName: "Barbara", FamilyName: "Jensen"},
+		Emails:   []model.ScimEmail{{Value: userName, Type: model.SCIM_EMAIL_TYPE_WORK, Primary: true}},
+	}
+}
+
+func TestCreateScimUser(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	userName := "bjensen" + model.NewId() + "@example.com"
+
+	created, err := th.App.CreateScimUser(newScimUser(userName))
+	require.Nil(t, err)
+	assert.Equal(t, userName, created.UserName)
+	assert.True(t, *created.Active)
+	assert.Equal(t, model.SCIM_RESOURCE_TYPE_USER, created.Meta.ResourceType)
+	assert.NotEmpty(t, created.Meta.Version)
+
+	user, err := th.App.GetUser(created.Id)
+	require.Nil(t, err)
+	assert.Equal(t, model.USER_AUTH_SERVICE_SAML, user.AuthService)
+	assert.Equal(t, userName, user.Email)
+	assert.True(t, user.EmailVerified)
+	assert.Equal(t, "Barbara", user.FirstName)
+
+	t.Run("existing user", func(t *testing.T) {
+		_, err := th.App.CreateScimUser(newScimUser(userName))
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusConflict, err.StatusCode)
+	})
+
+	t.Run("username taken", func(t *testing.T) {
+		other, err := th.App.CreateScimUser(newScimUser(user.Username + "@example.org"))
+		require.Nil(t, err)
+
+		otherUser, err := th.App.GetUser(other.Id)
+		require.Nil(t, err)
+		assert.Equal(t, user.Username+"-1", otherUser.Username)
+	})
+
+	t.Run("email taken", func(t *testing.T) {
+		_, err := th.App.CreateScimUser(newScimUser(th.BasicUser.Email))
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusConflict, err.StatusCode)
+	})
+
+	t.Run("missing userName", func(t *testing.T) {
+		_, err := th.App.CreateScimUser(newScimUser(""))
+		require.NotNil(t, err)
+		assert.Equal(t, "app.scim.user.user_name_required.app_error", err.Id)
+	})
+
+	t.Run("missing email", func(t *testing.T) {
+		_, err := th.App.CreateScimUser(&model.ScimUser{UserName: "user" + model.NewId()})
+		require.NotNil(t, err)
+		assert.Equal(t, "app.scim.user.email_required.app_error", err.Id)
+	})
+}
+
+func TestScimUserReprovisioning(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	userName := "bjensen" + model.NewId() + "@example.com"
+
+	created, err := th.App.CreateScimUser(newScimUser(userName))
+	require.Nil(t, err)
+
+	require.Nil(t, th.App.DeactivateScimUser(created.Id))
+	require.Nil(t, th.App.DeactivateScimUser(created.Id))
+
+	deactivated, err := th.App.GetScimUser(created.Id)
+	require.Nil(t, err)
+	assert.False(t, *deactivated.Active)
+
+	scimUser := newScimUser(userName)
+	scimUser.Name.FamilyName = "Smith"
+
+	reprovisioned, err := th.App.CreateScimUser(scimUser)
+	require.Nil(t, err)
+	assert.Equal(t, created.Id, reprovisioned.Id)
+	assert.True(t, *reprovisioned.Active)
+	assert.Equal(t, "Smith", reprovisioned.Name.FamilyName)
+}
+
+func TestPatchScimUser(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	created, err := th.App.CreateScimUser(newScimUser("bjensen" + model.NewId() + "@example.com"))
+	require.Nil(t, err)
+
+	newEmail := "bsmith" + model.NewId() + "@example.com"
+	patched, err := th.App.PatchScimUser(created.Id, &model.ScimPatchRequest{
+		Operations: []*model.ScimPatchOperation{
+			{Op: model.SCIM_PATCH_OP_REPLACE, Path: "userName", Value: newEmail},
+			{Op: model.SCIM_PATCH_OP_REPLACE, Path: "emails[type eq \"work\"].value", Value: newEmail},
+			{Op: model.SCIM_PATCH_OP_REPLACE, Path: "title", Value: "Engineer"},
+		},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, newEmail, patched.UserName)
+	assert.Equal(t, newEmail, patched.PrimaryEmail())
+	assert.Equal(t, "Engineer", patched.Title)
+
+	user, err := th.App.GetUser(created.Id)
+	require.Nil(t, err)
+	assert.Equal(t, newEmail, *user.AuthData)
+	assert.True(t, user.EmailVerified)
+
+	patched, err = th.App.PatchScimUser(created.Id, &model.ScimPatchRequest{
+		Operations: []*model.ScimPatchOperation{
+			{Op: model.SCIM_PATCH_OP_REPLACE, Value: map[string]interface{}{"active": false}},
+		},
+	})
+	require.Nil(t, err)
+	assert.False(t, *patched.Active)
+
+	_, err = th.App.PatchScimUser(model.NewId(), &model.ScimPatchRequest{})
+	require.NotNil(t, err)
+	assert.Equal(t, http.StatusNotFound, err.StatusCode)
+}
+
+func TestSearchScimUsers(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	userName := "bjensen" + model.NewId() + "@example.com"
+	created, err := th.App.CreateScimUser(newScimUser(userName))
+	require.Nil(t, err)
+
+	for _, filter := range []*model.ScimFilter{
+		{Attribute: "userName", Value: userName},
+		{Attribute: "externalId", Value: userName},
+		{Attribute: "emails.value", Value: userName},
+		{Attribute: "id", Value: created.Id},
+	} {
+		list, err := th.App.SearchScimUsers(filter, 1, model.SCIM_DEFAULT_COUNT)
+		require.Nil(t, err)
+		assert.Equal(t, 1, list.TotalResults, filter.Attribute)
+		if assert.Len(t, list.Resources, 1, filter.Attribute) {
+			assert.Equal(t, created.Id, list.Resources[0].(*model.ScimUser).Id)
+		}
+	}
+
+	list, err := th.App.SearchScimUsers(&model.ScimFilter{Attribute: "userName", Value: "missing" + model.NewId()}, 1, model.SCIM_DEFAULT_COUNT)
+	require.Nil(t, err)
+	assert.Equal(t, 0, list.TotalResults)
+	assert.Empty(t, list.Resources)
+
+	_, err = th.App.SearchScimUsers(&model.ScimFilter{Attribute: "title", Value: "Engineer"}, 1, model.SCIM_DEFAULT_COUNT)
+	require.NotNil(t, err)
+	assert.Equal(t, "app.scim.filter.unsupported_attribute.app_error", err.Id)
+
+	_, err = th.App.CreateScimUser(newScimUser("bsmith" + model.NewId() + "@example.com"))
+	require.Nil(t, err)
+
+	list, err = th.App.SearchScimUsers(nil, 1, 1)
+	require.Nil(t, err)
+	assert.True(t, list.TotalResults > 1)
+	assert.Len(t, list.Resources, 1)
+
+	second, err := th.App.SearchScimUsers(nil, 2, 1)
+	require.Nil(t, err)
+	assert.Equal(t, list.TotalResults, second.TotalResults)
+	if assert.Len(t, second.Resources, 1) {
+		assert.NotEqual(t, list.Resources[0].(*model.ScimUser).Id, second.Resources[0].(*model.ScimUser).Id)
+	}
+
+	list, err = th.App.SearchScimUsers(nil, 1, list.TotalResults)
+	require.Nil(t, err)
+	for _, resource := range list.Resources {
+		assert.NotEqual(t, th.BasicUser.Id, resource.(*model.ScimUser).Id, "users who sign in with a password shouldn't be listed")
+	}
+}
+
+func TestScimOnlyManagesScimUsers(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	replacement := newScimUser("attacker" + model.NewId() + "@example.com")
+
+	t.Run("users who sign in with a password", func(t *testing.T) {
+		user := th.BasicUser
+
+		_, err := th.App.GetScimUser(user.Id)
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusNotFound, err.StatusCode)
+
+		_, err = th.App.ReplaceScimUser(user.Id, replacement)
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusNotFound, err.StatusCode)
+
+		_, err = th.App.PatchScimUser(user.Id, &model.ScimPatchRequest{
+			Operations: []*model.ScimPatchOperation{{Op: model.SCIM_PATCH_OP_REPLACE, Path: "userName", Value: replacement.UserName}},
+		})
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusNotFound, err.StatusCode)
+
+		err = th.App.DeactivateScimUser(user.Id)
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusNotFound, err.StatusCode)
+
+		list, err := th.App.SearchScimUsers(&model.ScimFilter{Attribute: "emails.value", Value: user.Email}, 1, model.SCIM_DEFAULT_COUNT)
+		require.Nil(t, err)
+		assert.Equal(t, 0, list.TotalResults)
+
+		unchanged, err := th.App.GetUser(user.Id)
+		require.Nil(t, err)
+		assert.Equal(t, user.Email, unchanged.Email)
+		assert.Equal(t, "", unchanged.AuthService)
+		assert.Equal(t, int64(0), unchanged.DeleteAt)
+	})
+
+	t.Run("system admins", func(t *testing.T) {
+		created, err := th.App.CreateScimUser(newScimUser("admin" + model.NewId() + "@example.com"))
+		require.Nil(t, err)
+		_, err = th.App.UpdateUserRoles(created.Id, model.SYSTEM_USER_ROLE_ID+" "+model.SYSTEM_ADMIN_ROLE_ID, false)
+		require.Nil(t, err)
+
+		_, err = th.App.GetScimUser(created.Id)
+		assert.Nil(t, err, "system admins can still be read")
+
+		_, err = th.App.ReplaceScimUser(created.Id, replacement)
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusForbidden, err.StatusCode)
+
+		_, err = th.App.PatchScimUser(created.Id, &model.ScimPatchRequest{
+			Operations: []*model.ScimPatchOperation{{Op: model.SCIM_PATCH_OP_REPLACE, Path: "title", Value: "Intern"}},
+		})
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusForbidden, err.StatusCode)
+
+		err = th.App.DeactivateScimUser(created.Id)
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusForbidden, err.StatusCode)
+
+		unchanged, err := th.App.GetUser(created.Id)
+		require.Nil(t, err)
+		assert.Equal(t, created.UserName, unchanged.Email)
+		assert.Equal(t, int64(0), unchanged.DeleteAt)
+	})
+}
+
+func TestScimGroupsAsCustomGroups(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	displayName := "Engineering " + model.NewId()
+
+	created, err := th.App.CreateScimGroup(&model.ScimGroup{
+		DisplayName: displayName,
+		Members:     []model.ScimMember{{Value: th.BasicUser.Id}},
+	}, th.BasicUser.Id)
+	require.Nil(t, err)
+	assert.Equal(t, displayName, created.DisplayName)
+	assert.Equal(t, []string{th.BasicUser.Id}, created.MemberIds())
+
+	_, err = th.App.CreateScimGroup(&model.ScimGroup{DisplayName: displayName}, th.BasicUser.Id)
+	require.NotNil(t, err)
+	assert.Equal(t, http.StatusConflict, err.StatusCode)
+
+	patched, err := th.App.PatchScimGroup(created.Id, &model.ScimPatchRequest{
+		Operations: []*model.ScimPatchOperation{
+			{Op: model.SCIM_PATCH_OP_ADD, Path: "members", Value: []interface{}{map[string]interface{}{"value": th.BasicUser2.Id}}},
+			{Op: model.SCIM_PATCH_OP_REMOVE, Path: "members[value eq \"" + th.BasicUser.Id + "\"]"},
+		},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, []string{th.BasicUser2.Id}, patched.MemberIds())
+	assert.NotEqual(t, created.Meta.Version, patched.Meta.Version)
+
+	list, err := th.App.SearchScimGroups(&model.ScimFilter{Attribute: "displayName", Value: displayName}, 1, model.SCIM_DEFAULT_COUNT)
+	require.Nil(t, err)
+	if assert.Len(t, list.Resources, 1) {
+		assert.Equal(t, created.Id, list.Resources[0].(*model.ScimGroup).Id)
+	}
+
+	_, err = th.App.ReplaceScimGroup(created.Id, &model.ScimGroup{DisplayName: displayName, Members: []model.ScimMember{{Value: model.NewId()}}})
+	require.NotNil(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.StatusCode)
+
+	require.Nil(t, th.App.DeleteScimGroup(created.Id))
+
+	_, err = th.App.GetScimGroup(created.Id)
+	require.NotNil(t, err)
+	assert.Equal(t, http.StatusNotFound, err.StatusCode)
+}
+
+func TestScimGroupsAsTeams(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ScimSettings.GroupMapping = model.SCIM_GROUP_MAPPING_TEAMS })
+
+	user, err := th.App.CreateScimUser(newScimUser("bjensen" + model.NewId() + "@example.com"))
+	require.Nil(t, err)
+
+	created, err := th.App.CreateScimGroup(&model.ScimGroup{
+		DisplayName: th.BasicTeam.Name,
+		Members:     []model.ScimMember{{Value: user.Id}},
+	}, th.BasicUser.Id)
+	require.Nil(t, err)
+	assert.Equal(t, th.BasicTeam.Id, created.Id)
+	assert.Equal(t, []string{user.Id}, created.MemberIds())
+
+	_, err = th.App.GetTeamMember(th.BasicTeam.Id, user.Id)
+	require.Nil(t, err)
+
+	// the members of the group replace the existing members of the team that are managed by the identity provider
+	other, err := th.App.CreateScimUser(newScimUser("bsmith" + model.NewId() + "@example.com"))
+	require.Nil(t, err)
+	_, err = th.App.AddTeamMember(th.BasicTeam.Id, other.Id)
+	require.Nil(t, err)
+
+	admin, err := th.App.CreateScimUser(newScimUser("admin" + model.NewId() + "@example.com"))
+	require.Nil(t, err)
+	_, err = th.App.UpdateUserRoles(admin.Id, model.SYSTEM_USER_ROLE_ID+" "+model.SYSTEM_ADMIN_ROLE_ID, false)
+	require.Nil(t, err)
+	_, err = th.App.AddTeamMember(th.BasicTeam.Id, admin.Id)
+	require.Nil(t, err)
+
+	replaced, err := th.App.ReplaceScimGroup(th.BasicTeam.Id, &model.ScimGroup{
+		DisplayName: th.BasicTeam.Name,
+		Members:     []model.ScimMember{{Value: user.Id}},
+	})
+	require.Nil(t, err)
+	assert.NotContains(t, replaced.MemberIds(), th.BasicUser.Id, "users who sign in with a password aren't part of the group")
+
+	member, err := th.App.GetTeamMember(th.BasicTeam.Id, other.Id)
+	require.Nil(t, err)
+	assert.NotEqual(t, int64(0), member.DeleteAt)
+
+	member, err = th.App.GetTeamMember(th.BasicTeam.Id, th.BasicUser.Id)
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), member.DeleteAt, "users who sign in with a password should stay on the team")
+
+	member, err = th.App.GetTeamMember(th.BasicTeam.Id, admin.Id)
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), member.DeleteAt, "system admins should stay on the team")
+
+	_, err = th.App.CreateScimGroup(&model.ScimGroup{DisplayName: "missing" + model.NewId()}, th.BasicUser.Id)
+	require.NotNil(t, err)
+	assert.Equal(t, "app.scim.group.no_team.app_error", err.Id)
+
+	require.Nil(t, th.App.DeleteScimGroup(created.Id))
+
+	team, err := th.App.GetTeam(th.BasicTeam.Id)
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), team.DeleteAt)
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+type secretScanningRule struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// initSecretScanning compiles the configured secret scanning rules and recompiles them whenever the config changes.
+func (a *App) initSecretScanning() {
+	a.secretScanningRules.Store(compileSecretScanningRules(a.Config().ServiceSettings.SecretScanningRules))
+
+	a.secretScanListenerId = a.AddConfigListener(func(_, after *model.Config) {
+		a.secretScanningRules.Store(compileSecretScanningRules(after.ServiceSettings.SecretScanningRules))
+	})
+}
+
+// compileSecretScanningRules compiles rules in order of their names. Rules that don't compile are logged and
+// skipped, although the config shouldn't have been accepted with them.
+func compileSecretScanningRules(rules map[string]string) []*secretScanningRule {
+	compiled := make([]*secretScanningRule, 0, len(rules))
+
+	for name, rule := range rules {
+		pattern, err := regexp.Compile(rule)
+		if err != nil {
+			mlog.Error(fmt.Sprintf("Skipping invalid secret scanning rule name=%v, err=%v", name, err.Error()))
+			continue
+		}
+
+		compiled = append(compiled, &secretScanningRule{name: name, pattern: pattern})
+	}
+
+	sort.Slice(compiled, func(i, j int) bool {
+		return compiled[i].name < compiled[j].name
+	})
+
+	return compiled
+}
+
+// scanForSecrets returns the names of the rules that match text. If that takes longer than budget, it stops waiting
+// and returns false instead.
+func scanForSecrets(rules []*secretScanningRule, text string, budget time.Duration) ([]string, bool) {
+	done := make(chan []string, 1)
+
+	go func() {
+		matches := []string{}
+		for _, rule := range rules {
+			if rule.pattern.MatchString(text) {
+				matches = append(matches, rule.name)
+			}
+		}
+		done <- matches
+	}()
+
+	timer := time.NewTimer(budget)
+	defer timer.Stop()
+
+	select {
+	case matches := <-done:
+		return matches, true
+	case <-timer.C:
+		return nil, false
+	}
+}
+
+// checkPostForSecrets scans the message of a post that's about to be saved for anything that looks like a secret. An
+// error is returned if the post should be rejected because of it. Otherwise, the names of the rules that matched are
+// returned so that handleSecretsInPost can be called once the post is saved.
+//
+// Posts that take too long to scan are let through unscanned rather than being held up.
+func (a *App) checkPostForSecrets(post *model.Post) ([]string, *model.AppError) {
+	settings := a.Config().ServiceSettings
+	if *settings.SecretScanningMode == model.SECRET_SCANNING_MODE_OFF || post.IsSystemMessage() || post.Message == "" {
+		return nil, nil
+	}
+
+	rules := a.secretScanningRules.Load().([]*secretScanningRule)
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	budget := time.Duration(*settings.SecretScanningTimeBudgetMilliseconds) * time.Millisecond
+
+	matches, ok := scanForSecrets(rules, post.Message, budget)
+	if !ok {
+		mlog.Warn(fmt.Sprintf("Secret scanning took longer than %v so the post wasn't scanned, user_id=%v, channel_id=%v", budget, post.UserId, post.ChannelId))
+		return nil, nil
+	} else if len(matches) == 0 {
+		return nil, nil
+	}
+
+	if *settings.SecretScanningMode == model.SECRET_SCANNING_MODE_BLOCK {
+		return nil, model.NewAppError("checkPostForSecrets", "app.post.secret_scanning.blocked.app_error", map[string]interface{}{"Rules": strings.Join(matches, ", ")}, "user_id="+post.UserId+", channel_id="+post.ChannelId, http.StatusBadRequest)
+	}
+
+	return matches, nil
+}
+
+// handleSecretsInPost either warns the author of a saved post that it looks like it contains a secret, or alerts
+// the configured channel about it, depending on the secret scanning mode. The message itself is never repeated.
+func (a *App) handleSecretsInPost(post *model.Post, matches []string) {
+	if len(matches) == 0 {
+		return
+	}
+
+	user, err := a.GetUser(post.UserId)
+	if err != nil {
+		mlog.Error(fmt.Sprintf("Unable to handle secret scanning matches, post_id=%v, err=%v", post.Id, err.Error()), mlog.String("post_id", post.Id))
+		return
+	}
+
+	rules := strings.Join(matches, ", ")
+
+	switch *a.Config().ServiceSettings.SecretScanningMode {
+	case model.SECRET_SCANNING_MODE_WARN:
+		a.SendEphemeralPost(
+			post.UserId,
+			&model.Post{
+				ChannelId: post.ChannelId,
+				Message:   utils.GetUserTranslations(user.Locale)("app.post.secret_scanning.warning", map[string]interface{}{"Rules": rules}),
+				CreateAt:  post.CreateAt + 1,
+			},
+		)
+	case model.SECRET_SCANNING_MODE_ALERT:
+		if err := a.sendSecretScanningAlert(post, user, rules); err != nil {
+			mlog.Error(fmt.Sprintf("Unable to send secret scanning alert, post_id=%v, err=%v", post.Id, err.Error()), mlog.String("post_id", post.Id))
+		}
+	}
+}
+
+func (a *App) sendSecretScanningAlert(post *model.Post, user *model.User, rules string) *model.AppError {
+	channel, err := a.GetChannel(post.ChannelId)
+	if err != nil {
+		return err
+	}
+
+	alertChannel, err := a.GetChannel(*a.Config().ServiceSettings.SecretScanningAlertChannelId)
+	if err != nil {
+		return err
+	}
+
+	bot, err := a.EnsureSystemBot()
+	if err != nil {
+		return err
+	}
+
+	alert := &model.Post{
+		ChannelId: alertChannel.Id,
+		UserId:    bot.Id,
+		Message: utils.T("app.post.secret_scanning.alert", map[string]interface{}{
+			"Username":    user.Username,
+			"ChannelName": channel.Name,
+			"PostId":      post.Id,
+			"Rules":       rules,
+		}),
+	}
+
+	if _, err := a.CreatePost(alert, alertChannel, false); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// SecretRotation lists the secrets in the config to replace with new random values.
+type SecretRotation struct {
+	// OldAtRestEncryptKey is the at rest encryption key being replaced, which has to match the configured one. The
+	// key isn't rotated if this is empty.
+	OldAtRestEncryptKey string
+
+	// PublicLinkSalt replaces the public link salt, which invalidates every channel feed token and the public file
+	// links that were made from the salt before links were saved. Saved links keep working.
+	PublicLinkSalt bool
+}
+
+// SecretRotationResult counts what was affected by a secret rotation.
+type SecretRotationResult struct {
+	FeedTokensInvalidated int64
+
+	// ConfigVersionsReencrypted counts the saved versions of the config whose secrets are encrypted with the new at
+	// rest encryption key.
+	ConfigVersionsReencrypted int
+}
+
+// RotateSecrets replaces the requested secrets and saves them to the config in a single update once everything else
+// has been done, so the config is left as it was if anything fails.
+//
+// Replacing the at rest encryption key encrypts the secrets in the saved versions of the config again with the new
+// one before the config is saved, and fails if any of them can't be. They're put back as they were if the config
+// then can't be saved, since they'd otherwise be encrypted with a key that isn't configured.
+func (a *App) RotateSecrets(rotation *SecretRotation) (*SecretRotationResult, *model.AppError) {
+	cfg := a.Config().Clone()
+	result := &SecretRotationResult{}
+
+	if rotation.OldAtRestEncryptKey == "" && !rotation.PublicLinkSalt {
+		return nil, model.NewAppError("RotateSecrets", "app.security.rotate.nothing_to_rotate.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if rotation.OldAtRestEncryptKey != "" {
+		if rotation.OldAtRestEncryptKey != cfg.SqlSettings.AtRestEncryptKey {
+			return nil, model.NewAppError("RotateSecrets", "app.security.rotate.wrong_at_rest_encrypt_key.app_error", nil, "", http.StatusBadRequest)
+		}
+
+		cfg.SqlSettings.AtRestEncryptKey = model.NewRandomString(32)
+	}
+
+	if rotation.PublicLinkSalt {
+		// Feed tokens are signed with the salt rather than stored, so count the feeds that they could have been made
+		// for. The legacy public file links weren't stored either, and could have been made for any file, so they
+		// can't be counted.
+		feedsResult := <-a.Srv.Store.Channel().AnalyticsFeedCount()
+		if feedsResult.Err != nil {
+			return nil, feedsResult.Err
+		}
+		result.FeedTokensInvalidated = feedsResult.Data.(int64)
+
+		salt := model.NewRandomString(32)
+		cfg.FileSettings.PublicLinkSalt = &salt
+	}
+
+	var previousHistorySecrets map[string]string
+	if rotation.OldAtRestEncryptKey != "" {
+		var err *model.AppError
+		if previousHistorySecrets, err = a.reencryptConfigHistory(rotation.OldAtRestEncryptKey, cfg.SqlSettings.AtRestEncryptKey); err != nil {
+			return nil, err
+		}
+		result.ConfigVersionsReencrypted = len(previousHistorySecrets)
+	}
+
+	if err := a.SaveConfig(cfg, true); err != nil {
+		if previousHistorySecrets != nil {
+			if result := <-a.Srv.Store.ConfigurationHistory().UpdateSecrets(previousHistorySecrets); result.Err != nil {
+				mlog.Error("Failed to put back the secrets of the saved versions of the config after the config couldn't be saved", mlog.String("error", result.Err.Error()))
+			}
+		}
+		return nil, err
+	}
+
+	mlog.Info(fmt.Sprintf("Rotated secrets at_rest_encrypt_key=%v public_link_salt=%v feed_tokens_invalidated=%v config_versions_reencrypted=%v",
+		rotation.OldAtRestEncryptKey != "", rotation.PublicLinkSalt, result.FeedTokensInvalidated, result.ConfigVersionsReencrypted))
+
+	return result, nil
+}
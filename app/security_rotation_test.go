@@ -0,0 +1,145 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+// makeConfigHistoryReadableForTest replaces the secrets of the saved versions of the config that the other tests left
+// encrypted with their own at rest encryption keys, since they'd stop the current key from being rotated.
+func makeConfigHistoryReadableForTest(t *testing.T, th *TestHelper) {
+	key := th.App.Config().SqlSettings.AtRestEncryptKey
+	empty, err := utils.EncryptAtRest(key, []byte("{}"))
+	require.NoError(t, err)
+
+	unreadable := make(map[string]string)
+	for offset := 0; ; offset += CONFIGURATION_HISTORY_REENCRYPT_BATCH_SIZE {
+		list := store.Must(th.App.Srv.Store.ConfigurationHistory().GetPage(offset, CONFIGURATION_HISTORY_REENCRYPT_BATCH_SIZE)).([]*model.ConfigurationHistory)
+		for _, history := range list {
+			if _, err := utils.DecryptAtRest(key, history.Secrets); err != nil {
+				unreadable[history.Id] = empty
+			}
+		}
+
+		if len(list) < CONFIGURATION_HISTORY_REENCRYPT_BATCH_SIZE {
+			break
+		}
+	}
+
+	store.Must(th.App.Srv.Store.ConfigurationHistory().UpdateSecrets(unreadable))
+}
+
+func TestRotateSecrets(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	t.Run("nothing to rotate", func(t *testing.T) {
+		_, err := th.App.RotateSecrets(&SecretRotation{})
+		require.NotNil(t, err)
+		assert.Equal(t, "app.security.rotate.nothing_to_rotate.app_error", err.Id)
+	})
+
+	t.Run("at rest encryption key", func(t *testing.T) {
+		oldKey := th.App.Config().SqlSettings.AtRestEncryptKey
+		oldSalt := *th.App.Config().FileSettings.PublicLinkSalt
+
+		_, err := th.App.RotateSecrets(&SecretRotation{OldAtRestEncryptKey: model.NewRandomString(32)})
+		require.NotNil(t, err)
+		assert.Equal(t, "app.security.rotate.wrong_at_rest_encrypt_key.app_error", err.Id)
+		assert.Equal(t, oldKey, th.App.Config().SqlSettings.AtRestEncryptKey)
+
+		makeConfigHistoryReadableForTest(t, th)
+		_, err = th.App.RotateSecrets(&SecretRotation{OldAtRestEncryptKey: oldKey})
+		require.Nil(t, err)
+		assert.NotEqual(t, oldKey, th.App.Config().SqlSettings.AtRestEncryptKey)
+		assert.Len(t, th.App.Config().SqlSettings.AtRestEncryptKey, 32)
+		assert.Equal(t, oldSalt, *th.App.Config().FileSettings.PublicLinkSalt, "only the requested secrets should be rotated")
+
+		// The data is still readable with only the new key configured
+		_, err = th.App.GetChannel(th.BasicChannel.Id)
+		assert.Nil(t, err)
+	})
+
+	t.Run("unreadable config versions", func(t *testing.T) {
+		oldKey := th.App.Config().SqlSettings.AtRestEncryptKey
+		makeConfigHistoryReadableForTest(t, th)
+
+		readable, err := th.App.RecordConfigHistory(th.App.Config(), th.App.Config(), "", model.CONFIGURATION_HISTORY_SOURCE_SERVER)
+		require.Nil(t, err)
+
+		otherKeyCfg := th.App.Config().Clone()
+		otherKeyCfg.SqlSettings.AtRestEncryptKey = model.NewRandomString(32)
+		unreadable, err := th.App.RecordConfigHistory(th.App.Config(), otherKeyCfg, "", model.CONFIGURATION_HISTORY_SOURCE_SERVER)
+		require.Nil(t, err)
+
+		_, err = th.App.RotateSecrets(&SecretRotation{OldAtRestEncryptKey: oldKey})
+		require.NotNil(t, err)
+		assert.Equal(t, "app.configuration_history.decrypt.app_error", err.Id)
+		assert.Equal(t, oldKey, th.App.Config().SqlSettings.AtRestEncryptKey, "the key shouldn't be rotated")
+
+		// None of the versions are changed
+		history, err := th.App.GetConfigHistory(readable.Id)
+		require.Nil(t, err)
+		assert.Equal(t, readable.Secrets, history.Secrets)
+		history, err = th.App.GetConfigHistory(unreadable.Id)
+		require.Nil(t, err)
+		assert.Equal(t, unreadable.Secrets, history.Secrets)
+
+		makeConfigHistoryReadableForTest(t, th)
+	})
+
+	t.Run("public link salt", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) {
+			*cfg.ServiceSettings.EnableChannelFeeds = true
+		})
+
+		info, err := th.App.DoUploadFile(time.Now(), th.BasicTeam.Id, th.BasicChannel.Id, th.BasicUser.Id, "test.txt", []byte("data"))
+		require.Nil(t, err)
+		defer th.App.Srv.Store.FileInfo().PermanentDelete(info.Id)
+
+		channel := th.CreateChannel(th.BasicTeam)
+		channel.EnableFeed = true
+		channel, err = th.App.UpdateChannel(channel)
+		require.Nil(t, err)
+
+		_, err = th.App.CreatePostAsUser(&model.Post{
+			UserId:    th.BasicUser.Id,
+			ChannelId: th.BasicChannel.Id,
+			Message:   "file",
+			FileIds:   []string{info.Id},
+		})
+		require.Nil(t, err)
+
+		link, err := th.App.CreateFilePublicLink(&model.FilePublicLink{FileId: info.Id, CreatorId: th.BasicUser.Id})
+		require.Nil(t, err)
+
+		feeds := store.Must(th.App.Srv.Store.Channel().AnalyticsFeedCount()).(int64)
+
+		oldSalt := *th.App.Config().FileSettings.PublicLinkSalt
+		oldHash := GeneratePublicLinkHash(info.Id, oldSalt)
+		oldToken := GenerateChannelFeedToken(channel.Id, channel.FeedKey, oldSalt)
+
+		result, err := th.App.RotateSecrets(&SecretRotation{PublicLinkSalt: true})
+		require.Nil(t, err)
+		assert.Equal(t, feeds, result.FeedTokensInvalidated)
+		assert.True(t, result.FeedTokensInvalidated > 0)
+
+		// Saved links don't depend on the salt
+		assert.Nil(t, th.App.CountFilePublicLinkDownload(link.Id, info.Id))
+
+		newSalt := *th.App.Config().FileSettings.PublicLinkSalt
+		assert.NotEqual(t, oldSalt, newSalt)
+		assert.NotEqual(t, oldHash, GeneratePublicLinkHash(info.Id, newSalt))
+		assert.NotEqual(t, oldToken, GenerateChannelFeedToken(channel.Id, channel.FeedKey, newSalt))
+	})
+}
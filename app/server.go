@@ -12,7 +12,8 @@ import (
 	"net"
 	"net/http"
 	"os"
-	"strings"
+	"reflect"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/handlers"
@@ -32,18 +33,15 @@ type Server struct {
 	Router          *mux.Router
 	Server          *http.Server
 	ListenAddr      *net.TCPAddr
-	RateLimiter     *RateLimiter
 
-	didFinishListen chan struct{}
-}
+	// rateLimiter holds the *RateLimiter for the current config, which is nil while rate limiting is disabled
+	rateLimiter atomic.Value
+
+	// LocalRouter serves the API over the local mode socket.
+	LocalRouter *mux.Router
+	localServer *http.Server
 
-var allowedMethods []string = []string{
-	"POST",
-	"GET",
-	"OPTIONS",
-	"PUT",
-	"PATCH",
-	"DELETE",
+	didFinishListen chan struct{}
 }
 
 type RecoveryLogger struct {
@@ -54,35 +52,6 @@ func (rl *RecoveryLogger) Println(i ...interface{}) {
 	mlog.Error(fmt.Sprint(i))
 }
 
-type CorsWrapper struct {
-	config model.ConfigFunc
-	router *mux.Router
-}
-
-func (cw *CorsWrapper) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if allowed := *cw.config().ServiceSettings.AllowCorsFrom; allowed != "" {
-		if utils.CheckOrigin(r, allowed) {
-			w.Header().Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
-
-			if r.Method == "OPTIONS" {
-				w.Header().Set(
-					"Access-Control-Allow-Methods",
-					strings.Join(allowedMethods, ", "))
-
-				w.Header().Set(
-					"Access-Control-Allow-Headers",
-					r.Header.Get("Access-Control-Request-Headers"))
-			}
-		}
-	}
-
-	if r.Method == "OPTIONS" {
-		return
-	}
-
-	cw.router.ServeHTTP(w, r)
-}
-
 const TIME_TO_WAIT_FOR_CONNECTIONS_TO_CLOSE_ON_SERVER_SHUTDOWN = time.Second
 
 func redirectHTTPToHTTPS(w http.ResponseWriter, r *http.Request) {
@@ -96,22 +65,69 @@ func redirectHTTPToHTTPS(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, url.String(), http.StatusFound)
 }
 
+// RateLimiter returns the rate limiter for the current config, or nil if rate limiting is disabled.
+func (s *Server) RateLimiter() *RateLimiter {
+	rateLimiter, _ := s.rateLimiter.Load().(*RateLimiter)
+	return rateLimiter
+}
+
+// setupRateLimiter replaces the rate limiter with one for the given config. Requests that are already being rate
+// limited are forgotten, so it's only called when the settings that it uses change.
+func (a *App) setupRateLimiter(cfg *model.Config) error {
+	if !*cfg.RateLimitSettings.Enable {
+		a.Srv.rateLimiter.Store((*RateLimiter)(nil))
+		return nil
+	}
+
+	mlog.Info("RateLimiter is enabled")
+
+	rateLimiter, err := NewRateLimiter(&cfg.RateLimitSettings, &cfg.ServiceSettings)
+	if err != nil {
+		return err
+	}
+
+	a.Srv.rateLimiter.Store(rateLimiter)
+	return nil
+}
+
+func rateLimiterSettingsChanged(oldCfg, newCfg *model.Config) bool {
+	return !reflect.DeepEqual(oldCfg.RateLimitSettings, newCfg.RateLimitSettings) ||
+		*oldCfg.ServiceSettings.TrustedProxyIPHeader != *newCfg.ServiceSettings.TrustedProxyIPHeader ||
+		*oldCfg.ServiceSettings.TrustedProxyCIDRs != *newCfg.ServiceSettings.TrustedProxyCIDRs
+}
+
+// rateLimitHandler limits requests with whichever rate limiter is current when they're made, so that changes to the
+// rate limiting settings apply without restarting the server.
+func (a *App) rateLimitHandler(wrappedHandler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rateLimiter := a.Srv.RateLimiter(); rateLimiter != nil && rateLimiter.RateLimitWriter(rateLimiter.GenerateKey(r), w) {
+			return
+		}
+
+		wrappedHandler.ServeHTTP(w, r)
+	})
+}
+
 func (a *App) StartServer() error {
 	mlog.Info("Starting Server...")
 
-	var handler http.Handler = &CorsWrapper{a.Config, a.Srv.Router}
+	if err := a.setupRateLimiter(a.Config()); err != nil {
+		return err
+	}
 
-	if *a.Config().RateLimitSettings.Enable {
-		mlog.Info("RateLimiter is enabled")
+	a.rateLimiterListenerId = a.AddConfigListener(func(oldCfg, newCfg *model.Config) {
+		if !rateLimiterSettingsChanged(oldCfg, newCfg) {
+			return
+		}
 
-		rateLimiter, err := NewRateLimiter(&a.Config().RateLimitSettings)
-		if err != nil {
-			return err
+		// invalid rate limit settings keep the previous rate limiter rather than turning rate limiting off
+		if err := a.setupRateLimiter(newCfg); err != nil {
+			mlog.Error("Unable to update the rate limiter for the new config", mlog.Err(err))
 		}
+	})
 
-		a.Srv.RateLimiter = rateLimiter
-		handler = rateLimiter.RateLimitHandler(handler)
-	}
+	var handler http.Handler = &CorsWrapper{a.Config, a.Srv.Router}
+	handler = a.rateLimitHandler(handler)
 
 	a.Srv.Server = &http.Server{
 		Handler:      handlers.RecoveryHandler(handlers.RecoveryLogger(&RecoveryLogger{}), handlers.PrintRecoveryStack(true))(handler),
@@ -213,10 +229,24 @@ func (a *App) StartServer() error {
 		close(a.Srv.didFinishListen)
 	}()
 
+	if *a.Config().ServiceSettings.EnableLocalMode {
+		if err := a.startLocalModeServer(); err != nil {
+			mlog.Critical(err.Error())
+			return err
+		}
+	}
+
 	return nil
 }
 
 func (a *App) StopServer() {
+	a.stopLocalModeServer()
+
+	if a.rateLimiterListenerId != "" {
+		a.RemoveConfigListener(a.rateLimiterListenerId)
+		a.rateLimiterListenerId = ""
+	}
+
 	if a.Srv.Server != nil {
 		ctx, cancel := context.WithTimeout(context.Background(), TIME_TO_WAIT_FOR_CONNECTIONS_TO_CLOSE_ON_SERVER_SHUTDOWN)
 		defer cancel()
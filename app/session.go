@@ -11,6 +11,10 @@ import (
 	"github.com/mattermost/mattermost-server/model"
 )
 
+// SESSION_IDLE_TIMEOUT_ERROR_ID is the id of the error returned for sessions that were revoked for being idle for
+// longer than ServiceSettings.SessionIdleTimeoutInMinutes.
+const SESSION_IDLE_TIMEOUT_ERROR_ID = "api.context.session_idle_timeout.app_error"
+
 func (a *App) CreateSession(session *model.Session) (*model.Session, *model.AppError) {
 	session.Token = ""
 
@@ -18,6 +22,7 @@ func (a *App) CreateSession(session *model.Session) (*model.Session, *model.AppE
 		return nil, result.Err
 	} else {
 		session := result.Data.(*model.Session)
+		session.TermsOfServiceRequired = a.UserRequiresTermsOfService(session.UserId)
 
 		a.AddSessionToCache(session)
 
@@ -50,6 +55,7 @@ func (a *App) GetSession(token string) (*model.Session, *model.AppError) {
 				}
 
 				if !session.IsExpired() {
+					session.TermsOfServiceRequired = a.UserRequiresTermsOfService(session.UserId)
 					a.AddSessionToCache(session)
 				}
 			}
@@ -76,16 +82,21 @@ func (a *App) GetSession(token string) (*model.Session, *model.AppError) {
 		return nil, model.NewAppError("GetSession", "api.context.invalid_token.error", map[string]interface{}{"Token": token}, "", http.StatusUnauthorized)
 	}
 
-	license := a.License()
 	if *a.Config().ServiceSettings.SessionIdleTimeoutInMinutes > 0 &&
-		license != nil && *license.Features.Compliance &&
-		session != nil && !session.IsOAuth && !session.IsMobileApp() &&
+		!session.IsOAuth && !session.IsMobileApp() &&
 		session.Props[model.SESSION_PROP_TYPE] != model.SESSION_TYPE_USER_ACCESS_TOKEN {
 
+		// Activity is written to the database in batches, so a session that was just loaded from it may have been
+		// active more recently than it shows
+		lastActivityAt := session.LastActivityAt
+		if queued := a.getQueuedSessionActivity(session.Id); queued > lastActivityAt {
+			lastActivityAt = queued
+		}
+
 		timeout := int64(*a.Config().ServiceSettings.SessionIdleTimeoutInMinutes) * 1000 * 60
-		if model.GetMillis()-session.LastActivityAt > timeout {
+		if model.GetMillis()-lastActivityAt > timeout {
 			a.RevokeSessionById(session.Id)
-			return nil, model.NewAppError("GetSession", "api.context.invalid_token.error", map[string]interface{}{"Token": token}, "idle timeout", http.StatusUnauthorized)
+			return nil, model.NewAppError("GetSession", SESSION_IDLE_TIMEOUT_ERROR_ID, nil, "idle timeout", http.StatusUnauthorized)
 		}
 	}
 
@@ -116,6 +127,7 @@ func (a *App) RevokeAllSessions(userId string) *model.AppError {
 			}
 
 			a.RevokeWebrtcToken(session.Id)
+			a.revokeImpersonationSessions(session)
 		}
 	}
 
@@ -155,6 +167,23 @@ func (a *App) ClearSessionCacheForUserSkipClusterSend(userId string) {
 	a.InvalidateWebConnSessionCacheForUser(userId)
 }
 
+func (a *App) ClearSessionCacheForAllUsers() {
+	a.ClearSessionCacheForAllUsersSkipClusterSend()
+
+	if a.Cluster != nil {
+		msg := &model.ClusterMessage{
+			Event:    model.CLUSTER_EVENT_CLEAR_SESSION_CACHE_FOR_ALL_USERS,
+			SendType: model.CLUSTER_SEND_RELIABLE,
+		}
+		a.Cluster.SendClusterMessage(msg)
+	}
+}
+
+func (a *App) ClearSessionCacheForAllUsersSkipClusterSend() {
+	mlog.Info("Purging sessions cache")
+	a.sessionCache.Purge()
+}
+
 func (a *App) AddSessionToCache(session *model.Session) {
 	a.sessionCache.AddWithExpiresInSecs(session.Token, session, int64(*a.Config().ServiceSettings.SessionCacheInMinutes*60))
 }
@@ -213,10 +242,67 @@ func (a *App) RevokeSession(session *model.Session) *model.AppError {
 
 	a.RevokeWebrtcToken(session.Id)
 	a.ClearSessionCacheForUser(session.UserId)
+	a.revokeImpersonationSessions(session)
 
 	return nil
 }
 
+// CreateImpersonationSession creates a short lived session that lets a system admin act as another user
+// from their own session. Every request made with it is audited, and it is revoked with the admin's
+// session.
+func (a *App) CreateImpersonationSession(adminSession *model.Session, userId string) (*model.Session, *model.AppError) {
+	if !*a.Config().ServiceSettings.EnableUserImpersonation {
+		return nil, model.NewAppError("CreateImpersonationSession", "app.session.impersonate.disabled.app_error", nil, "", http.StatusNotImplemented)
+	}
+
+	if adminSession.IsImpersonation {
+		return nil, model.NewAppError("CreateImpersonationSession", "app.session.impersonate.nested.app_error", nil, "", http.StatusForbidden)
+	}
+
+	if adminSession.UserId == userId {
+		return nil, model.NewAppError("CreateImpersonationSession", "app.session.impersonate.self.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	user, err := a.GetUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.DeleteAt != 0 {
+		return nil, model.NewAppError("CreateImpersonationSession", "app.session.impersonate.inactive.app_error", nil, "user_id="+user.Id, http.StatusBadRequest)
+	}
+
+	session := &model.Session{
+		UserId:          user.Id,
+		Roles:           user.GetRawRoles(),
+		IsImpersonation: true,
+		ExpiresAt:       model.GetMillis() + int64(*a.Config().ServiceSettings.ImpersonationSessionLengthInMinutes)*60*1000,
+	}
+	session.AddProp(model.SESSION_PROP_IMPERSONATOR_ID, adminSession.UserId)
+	session.AddProp(model.SESSION_PROP_IMPERSONATOR_SESSION_ID, adminSession.Id)
+
+	return a.CreateSession(session)
+}
+
+func (a *App) revokeImpersonationSessions(impersonatorSession *model.Session) {
+	if impersonatorSession.IsImpersonation {
+		return
+	}
+
+	result := <-a.Srv.Store.Session().GetImpersonationSessions(impersonatorSession.Id)
+	if result.Err != nil {
+		mlog.Error(fmt.Sprintf("Failed to get impersonation sessions for session_id=%v, err=%v", impersonatorSession.Id, result.Err))
+		return
+	}
+
+	for _, session := range result.Data.([]*model.Session) {
+		if err := a.RevokeSession(session); err != nil {
+			// Soft error so we still remove the other sessions
+			mlog.Error(err.Error())
+		}
+	}
+}
+
 func (a *App) AttachDeviceId(sessionId string, deviceId string, expiresAt int64) *model.AppError {
 	if result := <-a.Srv.Store.Session().UpdateDeviceId(sessionId, deviceId, expiresAt); result.Err != nil {
 		return result.Err
@@ -226,14 +312,17 @@ func (a *App) AttachDeviceId(sessionId string, deviceId string, expiresAt int64)
 }
 
 func (a *App) UpdateLastActivityAtIfNeeded(session model.Session) {
+	// An admin using an impersonation session isn't activity by the user being impersonated
+	if session.IsImpersonation {
+		return
+	}
+
 	now := model.GetMillis()
 	if now-session.LastActivityAt < model.SESSION_ACTIVITY_TIMEOUT {
 		return
 	}
 
-	if result := <-a.Srv.Store.Session().UpdateLastActivityAt(session.Id, now); result.Err != nil {
-		mlog.Error(fmt.Sprintf("Failed to update LastActivityAt for user_id=%v and session_id=%v, err=%v", session.UserId, session.Id, result.Err), mlog.String("user_id", session.UserId))
-	}
+	a.queueSessionActivity(&session, now)
 
 	session.LastActivityAt = now
 	a.AddSessionToCache(&session)
@@ -258,7 +347,7 @@ func (a *App) CreateUserAccessToken(token *model.UserAccessToken) (*model.UserAc
 		mlog.Error(result.Err.Error())
 	} else {
 		user := result.Data.(*model.User)
-		if err := a.SendUserAccessTokenAddedEmail(user.Email, user.Locale); err != nil {
+		if err := a.SendUserAccessTokenAddedEmail(user.Email, user.Locale, a.GetUserTimezoneLocation(user)); err != nil {
 			mlog.Error(err.Error())
 		}
 	}
@@ -0,0 +1,101 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const (
+	SESSION_ACTIVITY_FLUSH_TASK_NAME = "Flush Session Activity"
+	SESSION_ACTIVITY_FLUSH_INTERVAL  = time.Minute
+	SESSION_ACTIVITY_BATCH_SIZE      = 1000
+)
+
+// startSessionActivityFlush periodically writes the activity recorded by UpdateLastActivityAtIfNeeded to the
+// database so that each request doesn't have to.
+func (a *App) startSessionActivityFlush() {
+	a.sessionActivityLock.Lock()
+	defer a.sessionActivityLock.Unlock()
+
+	a.sessionActivity = make(map[string]int64)
+	a.userActivity = make(map[string]int64)
+	a.sessionActivityTask = model.CreateRecurringTask(SESSION_ACTIVITY_FLUSH_TASK_NAME, a.FlushSessionActivity, SESSION_ACTIVITY_FLUSH_INTERVAL)
+}
+
+func (a *App) stopSessionActivityFlush() {
+	a.sessionActivityLock.Lock()
+	task := a.sessionActivityTask
+	a.sessionActivityTask = nil
+	a.sessionActivityLock.Unlock()
+
+	if task != nil {
+		task.Cancel()
+	}
+
+	a.FlushSessionActivity()
+}
+
+func (a *App) queueSessionActivity(session *model.Session, lastActivityAt int64) {
+	a.sessionActivityLock.Lock()
+	defer a.sessionActivityLock.Unlock()
+
+	if a.sessionActivity == nil {
+		a.sessionActivity = make(map[string]int64)
+		a.userActivity = make(map[string]int64)
+	}
+
+	a.sessionActivity[session.Id] = lastActivityAt
+	a.userActivity[session.UserId] = lastActivityAt
+}
+
+// getQueuedSessionActivity returns the last activity of a session that hasn't been written to the database yet,
+// or 0 if there isn't any.
+func (a *App) getQueuedSessionActivity(sessionId string) int64 {
+	a.sessionActivityLock.Lock()
+	defer a.sessionActivityLock.Unlock()
+
+	return a.sessionActivity[sessionId]
+}
+
+// FlushSessionActivity writes the session activity that hasn't been written to the database yet in batches.
+func (a *App) FlushSessionActivity() {
+	a.sessionActivityLock.Lock()
+	sessionActivity := a.sessionActivity
+	userActivity := a.userActivity
+	a.sessionActivity = make(map[string]int64)
+	a.userActivity = make(map[string]int64)
+	a.sessionActivityLock.Unlock()
+
+	batch := make(map[string]int64, SESSION_ACTIVITY_BATCH_SIZE)
+	for sessionId, lastActivityAt := range sessionActivity {
+		batch[sessionId] = lastActivityAt
+
+		if len(batch) == SESSION_ACTIVITY_BATCH_SIZE {
+			a.flushSessionActivityBatch(batch)
+			batch = make(map[string]int64, SESSION_ACTIVITY_BATCH_SIZE)
+		}
+	}
+	a.flushSessionActivityBatch(batch)
+
+	for userId, lastSeenAt := range userActivity {
+		if result := <-a.Srv.Store.User().UpdateLastSeenAt(userId, lastSeenAt); result.Err != nil {
+			mlog.Error(fmt.Sprintf("Failed to update LastSeenAt for user_id=%v, err=%v", userId, result.Err), mlog.String("user_id", userId))
+		}
+	}
+}
+
+func (a *App) flushSessionActivityBatch(batch map[string]int64) {
+	if len(batch) == 0 {
+		return
+	}
+
+	if result := <-a.Srv.Store.Session().UpdateLastActivityAtBatch(batch); result.Err != nil {
+		mlog.Error(fmt.Sprintf("Failed to update LastActivityAt for %v sessions, err=%v", len(batch), result.Err))
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/avct/uasurfer"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const (
+	SESSION_FINGERPRINT_IPV4_PREFIX_LENGTH = 24
+	SESSION_FINGERPRINT_IPV6_PREFIX_LENGTH = 48
+)
+
+// getUserAgentFamily returns the platform and browser of a user agent without their versions, so that the
+// family stays the same when the client is updated.
+func getUserAgentFamily(userAgent string) string {
+	ua := uasurfer.Parse(userAgent)
+	return getPlatformName(ua) + "/" + getBrowserName(ua, userAgent)
+}
+
+// getIpAddressPrefix returns the network that an IP address belongs to, or an empty string if it isn't valid.
+func getIpAddressPrefix(ipAddress string) string {
+	ip := net.ParseIP(ipAddress)
+	if ip == nil {
+		return ""
+	}
+
+	mask := net.CIDRMask(SESSION_FINGERPRINT_IPV6_PREFIX_LENGTH, 8*net.IPv6len)
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		mask = net.CIDRMask(SESSION_FINGERPRINT_IPV4_PREFIX_LENGTH, 8*net.IPv4len)
+	}
+
+	return (&net.IPNet{IP: ip.Mask(mask), Mask: mask}).String()
+}
+
+// AddSessionFingerprint records the client that a session is being created for so that requests made with
+// it from elsewhere can be detected.
+func (a *App) AddSessionFingerprint(session *model.Session, r *http.Request) {
+	session.AddProp(model.SESSION_PROP_FINGERPRINT_AGENT, getUserAgentFamily(r.UserAgent()))
+	session.AddProp(model.SESSION_PROP_FINGERPRINT_IP, getIpAddressPrefix(a.GetIpAddress(r)))
+}
+
+func (a *App) isAllowedSessionAnomalyIp(ip net.IP) bool {
+	for _, allowed := range strings.Fields(*a.Config().ServiceSettings.SessionAnomalyAllowedCIDRs) {
+		if _, ipRange, err := net.ParseCIDR(allowed); err == nil && ipRange.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isSessionAnomaly returns true if a request doesn't match the client that the session was created for.
+// Moving between addresses that are both in an allowed range, such as a carrier's NAT pool, isn't an anomaly.
+func (a *App) isSessionAnomaly(session *model.Session, ipAddress, userAgent string) bool {
+	agent, ok := session.Props[model.SESSION_PROP_FINGERPRINT_AGENT]
+	if !ok {
+		// Sessions created before fingerprints were recorded, or without signing in, can't be checked
+		return false
+	}
+
+	if getUserAgentFamily(userAgent) != agent {
+		return true
+	}
+
+	ip := net.ParseIP(ipAddress)
+	originalIp, originalRange, err := net.ParseCIDR(session.Props[model.SESSION_PROP_FINGERPRINT_IP])
+	if ip == nil || err != nil {
+		return false
+	}
+
+	if originalRange.Contains(ip) {
+		return false
+	}
+
+	return !a.isAllowedSessionAnomalyIp(originalIp) || !a.isAllowedSessionAnomalyIp(ip)
+}
+
+// CheckSessionFingerprint compares a request with the client that its session was created for when session
+// anomaly detection is enabled. If they're drastically different, the user is notified by email and the
+// anomaly is audited. In strict mode the session is also revoked and an error is returned so that the user
+// has to sign in again, while in lax mode the user is only notified the first time.
+func (a *App) CheckSessionFingerprint(session *model.Session, r *http.Request) *model.AppError {
+	return a.checkSessionFingerprint(session, a.GetIpAddress(r), r.UserAgent(), r.URL.Path)
+}
+
+// CheckWebConnSessionFingerprint is like CheckSessionFingerprint for a session that a websocket connection
+// authenticated with, compared against the request that the connection was upgraded from.
+func (a *App) CheckWebConnSessionFingerprint(session *model.Session, conn *WebConn) *model.AppError {
+	return a.checkSessionFingerprint(session, conn.IpAddress, conn.UserAgent, model.API_URL_SUFFIX+"/websocket")
+}
+
+func (a *App) checkSessionFingerprint(session *model.Session, ipAddress, userAgent, action string) *model.AppError {
+	if !*a.Config().ServiceSettings.SessionAnomalyDetection {
+		return nil
+	}
+
+	if !a.isSessionAnomaly(session, ipAddress, userAgent) {
+		return nil
+	}
+
+	strict := *a.Config().ServiceSettings.SessionAnomalyDetectionMode == model.SESSION_ANOMALY_DETECTION_MODE_STRICT
+	if !strict && session.Props[model.SESSION_PROP_ANOMALY_DETECTED_AT] != "" {
+		return nil
+	}
+
+	agent := getUserAgentFamily(userAgent)
+
+	audit := &model.Audit{
+		UserId:    session.UserId,
+		IpAddress: ipAddress,
+		Action:    action,
+		ExtraInfo: fmt.Sprintf("session anomaly agent=%v original_agent=%v original_ip=%v mode=%v", agent, session.Props[model.SESSION_PROP_FINGERPRINT_AGENT], session.Props[model.SESSION_PROP_FINGERPRINT_IP], *a.Config().ServiceSettings.SessionAnomalyDetectionMode),
+		SessionId: session.Id,
+	}
+	if result := <-a.Srv.Store.Audit().Save(audit); result.Err != nil {
+		mlog.Error("Failed to audit a session anomaly", mlog.String("session_id", session.Id), mlog.Err(result.Err))
+	}
+
+	if strict {
+		if err := a.RevokeSession(session); err != nil {
+			return err
+		}
+	} else {
+		// The cached session is shared between requests, so it's copied before being changed
+		props := model.CopyStringMap(session.Props)
+		props[model.SESSION_PROP_ANOMALY_DETECTED_AT] = strconv.FormatInt(model.GetMillis(), 10)
+
+		if result := <-a.Srv.Store.Session().UpdateProps(session.Id, props); result.Err != nil {
+			return result.Err
+		}
+
+		a.ClearSessionCacheForUser(session.UserId)
+	}
+
+	a.Go(func() {
+		user, err := a.GetUser(session.UserId)
+		if err != nil {
+			mlog.Error("Failed to get the user to notify of a session anomaly", mlog.String("user_id", session.UserId), mlog.Err(err))
+			return
+		}
+
+		if err := a.SendSessionAnomalyEmail(user.Email, agent, ipAddress, strict, user.Locale, a.GetSiteURL()); err != nil {
+			mlog.Error("Failed to notify the user of a session anomaly", mlog.String("user_id", user.Id), mlog.Err(err))
+		}
+	})
+
+	if strict {
+		return model.NewAppError("checkSessionFingerprint", "api.context.session_expired.app_error", nil, "session_id="+session.Id+", the request didn't match the session's fingerprint", http.StatusUnauthorized)
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const (
+	testChromeUserAgent  = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_12_6) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/60.0.3112.90 Safari/537.36"
+	testFirefoxUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:57.0) Gecko/20100101 Firefox/57.0"
+)
+
+func newFingerprintRequest(ipAddress, userAgent string) *http.Request {
+	r := httptest.NewRequest("GET", "/api/v4/users/me", nil)
+	r.RemoteAddr = ipAddress + ":4321"
+	r.Header.Set("User-Agent", userAgent)
+	return r
+}
+
+func TestGetIpAddressPrefix(t *testing.T) {
+	assert.Equal(t, "192.168.17.0/24", getIpAddressPrefix("192.168.17.42"))
+	assert.Equal(t, "2001:db8:1234::/48", getIpAddressPrefix("2001:db8:1234:5678::1"))
+	assert.Equal(t, "", getIpAddressPrefix("junk"))
+}
+
+func TestCheckSessionFingerprint(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	createSession := func() *model.Session {
+		session := &model.Session{UserId: th.BasicUser.Id}
+		th.App.AddSessionFingerprint(session, newFingerprintRequest("10.1.2.3", testChromeUserAgent))
+
+		session, err := th.App.CreateSession(session)
+		require.Nil(t, err)
+		return session
+	}
+
+	session := createSession()
+	assert.Equal(t, "Macintosh/Chrome", session.Props[model.SESSION_PROP_FINGERPRINT_AGENT])
+	assert.Equal(t, "10.1.2.0/24", session.Props[model.SESSION_PROP_FINGERPRINT_IP])
+
+	// Nothing is checked while detection is disabled
+	assert.Nil(t, th.App.CheckSessionFingerprint(session, newFingerprintRequest("172.16.0.1", testFirefoxUserAgent)))
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.ServiceSettings.SessionAnomalyDetection = true
+		*cfg.ServiceSettings.SessionAnomalyDetectionMode = model.SESSION_ANOMALY_DETECTION_MODE_LAX
+	})
+
+	t.Run("lax", func(t *testing.T) {
+		session := createSession()
+
+		assert.Nil(t, th.App.CheckSessionFingerprint(session, newFingerprintRequest("10.1.2.200", testChromeUserAgent)))
+
+		session, err := th.App.GetSession(session.Token)
+		require.Nil(t, err)
+		assert.Empty(t, session.Props[model.SESSION_PROP_ANOMALY_DETECTED_AT], "a nearby address shouldn't be an anomaly")
+
+		assert.Nil(t, th.App.CheckSessionFingerprint(session, newFingerprintRequest("172.16.0.1", testChromeUserAgent)))
+
+		session, err = th.App.GetSession(session.Token)
+		require.Nil(t, err, "lax mode shouldn't revoke the session")
+		assert.NotEmpty(t, session.Props[model.SESSION_PROP_ANOMALY_DETECTED_AT])
+
+		result := <-th.App.Srv.Store.Audit().Get(th.BasicUser.Id, 0, 10)
+		require.Nil(t, result.Err)
+		audits := result.Data.(model.Audits)
+		require.NotEmpty(t, audits)
+		assert.Equal(t, session.Id, audits[0].SessionId)
+		assert.Contains(t, audits[0].ExtraInfo, "session anomaly")
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) {
+			*cfg.ServiceSettings.SessionAnomalyDetectionMode = model.SESSION_ANOMALY_DETECTION_MODE_STRICT
+		})
+
+		session := createSession()
+
+		assert.Nil(t, th.App.CheckSessionFingerprint(session, newFingerprintRequest("10.1.2.3", testChromeUserAgent)))
+
+		err := th.App.CheckSessionFingerprint(session, newFingerprintRequest("10.1.2.3", testFirefoxUserAgent))
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusUnauthorized, err.StatusCode)
+
+		_, err = th.App.GetSession(session.Token)
+		assert.NotNil(t, err, "strict mode should revoke the session")
+	})
+
+	t.Run("websocket", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) {
+			*cfg.ServiceSettings.SessionAnomalyDetectionMode = model.SESSION_ANOMALY_DETECTION_MODE_STRICT
+		})
+
+		session := createSession()
+
+		assert.Nil(t, th.App.CheckWebConnSessionFingerprint(session, &WebConn{IpAddress: "10.1.2.3", UserAgent: testChromeUserAgent}))
+
+		err := th.App.CheckWebConnSessionFingerprint(session, &WebConn{IpAddress: "172.16.0.1", UserAgent: testChromeUserAgent})
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusUnauthorized, err.StatusCode)
+
+		_, err = th.App.GetSession(session.Token)
+		assert.NotNil(t, err, "strict mode should revoke the session")
+	})
+
+	t.Run("allowed ranges", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) {
+			*cfg.ServiceSettings.SessionAnomalyDetectionMode = model.SESSION_ANOMALY_DETECTION_MODE_STRICT
+			*cfg.ServiceSettings.SessionAnomalyAllowedCIDRs = "10.0.0.0/8"
+		})
+
+		session := createSession()
+
+		assert.Nil(t, th.App.CheckSessionFingerprint(session, newFingerprintRequest("10.200.0.1", testChromeUserAgent)))
+		assert.NotNil(t, th.App.CheckSessionFingerprint(session, newFingerprintRequest("172.16.0.1", testChromeUserAgent)))
+	})
+}
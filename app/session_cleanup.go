@@ -0,0 +1,150 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const (
+	// Deletes are spread out so that cleaning up a large Sessions table doesn't hold up other queries.
+	CLEANUP_BATCH_DELAY_MILLISECONDS = 100
+
+	SESSIONS_CLEANED_UP_REASON_EXPIRED  = "expired"
+	SESSIONS_CLEANED_UP_REASON_ORPHANED = "orphaned"
+)
+
+// CleanupSessions deletes the sessions that expired more than SessionCleanupRetentionDays ago and the sessions of
+// users that have been deleted or deactivated. It returns how many were deleted.
+func (a *App) CleanupSessions() (int64, *model.AppError) {
+	batchSize := *a.Config().ServiceSettings.SessionCleanupBatchSize
+	expiredBefore := model.GetMillis() - int64(*a.Config().ServiceSettings.SessionCleanupRetentionDays)*24*60*60*1000
+
+	expired, err := a.PurgeExpiredSessions(expiredBefore, batchSize)
+	if err != nil {
+		return expired, err
+	}
+
+	orphaned, err := a.PurgeOrphanedSessions(batchSize)
+
+	return expired + orphaned, err
+}
+
+// PurgeExpiredSessions deletes the sessions that expired before the given time, batchSize at a time, and returns how
+// many were deleted.
+func (a *App) PurgeExpiredSessions(expiredBefore int64, batchSize int) (int64, *model.AppError) {
+	return a.purgeSessions(SESSIONS_CLEANED_UP_REASON_EXPIRED, func() ([]*model.Session, *model.AppError) {
+		result := <-a.Srv.Store.Session().PermanentDeleteExpiredBatch(expiredBefore, int64(batchSize))
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		return result.Data.([]*model.Session), nil
+	})
+}
+
+// PurgeOrphanedSessions deletes the sessions of users that have been deleted or deactivated, batchSize at a time, and
+// returns how many were deleted.
+func (a *App) PurgeOrphanedSessions(batchSize int) (int64, *model.AppError) {
+	return a.purgeSessions(SESSIONS_CLEANED_UP_REASON_ORPHANED, func() ([]*model.Session, *model.AppError) {
+		result := <-a.Srv.Store.Session().PermanentDeleteOrphanedBatch(int64(batchSize))
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		return result.Data.([]*model.Session), nil
+	})
+}
+
+func (a *App) purgeSessions(reason string, deleteBatch func() ([]*model.Session, *model.AppError)) (int64, *model.AppError) {
+	var deleted int64
+	for {
+		sessions, err := deleteBatch()
+		if err != nil {
+			return deleted, err
+		}
+
+		if len(sessions) == 0 {
+			break
+		}
+
+		sessionIds := make([]string, len(sessions))
+		for i, session := range sessions {
+			sessionIds[i] = session.Id
+		}
+		a.clearSessionCacheForSessions(sessionIds, true)
+
+		deleted += int64(len(sessions))
+		if a.Metrics != nil {
+			a.Metrics.AddSessionsCleanedUp(reason, int64(len(sessions)))
+		}
+
+		time.Sleep(CLEANUP_BATCH_DELAY_MILLISECONDS * time.Millisecond)
+	}
+
+	if deleted > 0 {
+		mlog.Info(fmt.Sprintf("Deleted %v %v sessions", deleted, reason))
+	}
+
+	return deleted, nil
+}
+
+// PurgeAudits deletes the audits created before the given time, batchSize at a time, and returns how many were
+// deleted.
+func (a *App) PurgeAudits(createdBefore int64, batchSize int) (int64, *model.AppError) {
+	var deleted int64
+	for {
+		result := <-a.Srv.Store.Audit().PermanentDeleteBatch(createdBefore, int64(batchSize))
+		if result.Err != nil {
+			return deleted, result.Err
+		}
+
+		count := result.Data.(int64)
+		if count == 0 {
+			break
+		}
+		deleted += count
+
+		time.Sleep(CLEANUP_BATCH_DELAY_MILLISECONDS * time.Millisecond)
+	}
+
+	return deleted, nil
+}
+
+// clearSessionCacheForSessions removes only the given sessions from the session cache so that the cache can't keep
+// using a session after it's deleted. Session ids are sent to the rest of the cluster instead of tokens.
+func (a *App) clearSessionCacheForSessions(sessionIds []string, sendToCluster bool) {
+	ids := make(map[string]bool, len(sessionIds))
+	for _, sessionId := range sessionIds {
+		ids[sessionId] = true
+	}
+
+	userIds := make(map[string]bool)
+	for _, key := range a.sessionCache.Keys() {
+		if ts, ok := a.sessionCache.Get(key); ok {
+			session := ts.(*model.Session)
+			if ids[session.Id] {
+				a.sessionCache.Remove(key)
+				userIds[session.UserId] = true
+				if a.Metrics != nil {
+					a.Metrics.IncrementMemCacheInvalidationCounterSession()
+				}
+			}
+		}
+	}
+
+	for userId := range userIds {
+		a.InvalidateWebConnSessionCacheForUser(userId)
+	}
+
+	if sendToCluster && a.Cluster != nil {
+		a.Cluster.SendClusterMessage(&model.ClusterMessage{
+			Event:    model.CLUSTER_EVENT_CLEAR_SESSION_CACHE_FOR_SESSIONS,
+			SendType: model.CLUSTER_SEND_RELIABLE,
+			Data:     model.ArrayToJson(sessionIds),
+		})
+	}
+}
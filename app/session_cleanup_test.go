@@ -0,0 +1,70 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestPurgeExpiredSessions(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	expired, err := th.App.CreateSession(&model.Session{UserId: th.BasicUser.Id, ExpiresAt: model.GetMillis() - 60*1000})
+	require.Nil(t, err)
+
+	live, err := th.App.CreateSession(&model.Session{UserId: th.BasicUser.Id, ExpiresAt: model.GetMillis() + 60*60*1000})
+	require.Nil(t, err)
+
+	_, ok := th.App.sessionCache.Get(expired.Token)
+	require.True(t, ok)
+
+	deleted, err := th.App.PurgeExpiredSessions(model.GetMillis(), 1)
+	require.Nil(t, err)
+	assert.True(t, deleted >= 1)
+
+	_, ok = th.App.sessionCache.Get(expired.Token)
+	assert.False(t, ok, "the expired session should have been removed from the cache")
+
+	_, ok = th.App.sessionCache.Get(live.Token)
+	assert.True(t, ok, "the live session should still be cached")
+
+	result := <-th.App.Srv.Store.Session().Get(expired.Id)
+	assert.NotNil(t, result.Err, "the expired session should have been deleted")
+
+	result = <-th.App.Srv.Store.Session().Get(live.Id)
+	assert.Nil(t, result.Err, "the live session shouldn't have been deleted")
+}
+
+func TestPurgeOrphanedSessions(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	user := th.CreateUser()
+	orphaned, err := th.App.CreateSession(&model.Session{UserId: user.Id, ExpiresAt: model.GetMillis() + 60*60*1000})
+	require.Nil(t, err)
+
+	live, err := th.App.CreateSession(&model.Session{UserId: th.BasicUser.Id, ExpiresAt: model.GetMillis() + 60*60*1000})
+	require.Nil(t, err)
+
+	result := <-th.App.Srv.Store.User().PermanentDelete(user.Id)
+	require.Nil(t, result.Err)
+
+	_, err = th.App.PurgeOrphanedSessions(10)
+	require.Nil(t, err)
+
+	_, ok := th.App.sessionCache.Get(orphaned.Token)
+	assert.False(t, ok)
+
+	_, ok = th.App.sessionCache.Get(live.Token)
+	assert.True(t, ok)
+
+	result = <-th.App.Srv.Store.Session().Get(orphaned.Id)
+	assert.NotNil(t, result.Err)
+}
@@ -47,7 +47,6 @@ func TestGetSessionIdleTimeoutInMinutes(t *testing.T) {
 
 	session, _ = th.App.CreateSession(session)
 
-	th.App.SetLicense(model.NewTestLicense("compliance"))
 	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.SessionIdleTimeoutInMinutes = 5 })
 
 	rsession, err := th.App.GetSession(session.Token)
@@ -63,7 +62,7 @@ func TestGetSessionIdleTimeoutInMinutes(t *testing.T) {
 
 	rsession, err = th.App.GetSession(session.Token)
 	require.NotNil(t, err)
-	assert.Equal(t, "api.context.invalid_token.error", err.Id)
+	assert.Equal(t, SESSION_IDLE_TIMEOUT_ERROR_ID, err.Id)
 	assert.Equal(t, "idle timeout", err.DetailedError)
 	assert.Nil(t, rsession)
 
@@ -109,9 +108,25 @@ func TestGetSessionIdleTimeoutInMinutes(t *testing.T) {
 	_, err = th.App.GetSession(session.Token)
 	assert.Nil(t, err)
 
-	// Test regular session with license off, should not timeout
+	// Test regular session with a license, should timeout the same as without one
+	th.App.SetLicense(model.NewTestLicense("compliance"))
+
+	session = &model.Session{
+		UserId: model.NewId(),
+	}
+
+	session, _ = th.App.CreateSession(session)
+	time = session.LastActivityAt - (1000 * 60 * 6)
+	<-th.App.Srv.Store.Session().UpdateLastActivityAt(session.Id, time)
+	th.App.ClearSessionCacheForUserSkipClusterSend(session.UserId)
+
+	_, err = th.App.GetSession(session.Token)
+	require.NotNil(t, err)
+	assert.Equal(t, SESSION_IDLE_TIMEOUT_ERROR_ID, err.Id)
+
 	th.App.SetLicense(nil)
 
+	// Test regular session with activity that hasn't been written to the database yet, should not timeout
 	session = &model.Session{
 		UserId: model.NewId(),
 	}
@@ -119,13 +134,13 @@ func TestGetSessionIdleTimeoutInMinutes(t *testing.T) {
 	session, _ = th.App.CreateSession(session)
 	time = session.LastActivityAt - (1000 * 60 * 6)
 	<-th.App.Srv.Store.Session().UpdateLastActivityAt(session.Id, time)
+	session.LastActivityAt = time
+	th.App.UpdateLastActivityAtIfNeeded(*session)
 	th.App.ClearSessionCacheForUserSkipClusterSend(session.UserId)
 
 	_, err = th.App.GetSession(session.Token)
 	assert.Nil(t, err)
 
-	th.App.SetLicense(model.NewTestLicense("compliance"))
-
 	// Test regular session with timeout set to 0, should not timeout
 	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.SessionIdleTimeoutInMinutes = 0 })
 
@@ -141,3 +156,101 @@ func TestGetSessionIdleTimeoutInMinutes(t *testing.T) {
 	_, err = th.App.GetSession(session.Token)
 	assert.Nil(t, err)
 }
+
+func TestUpdateLastActivityAtIfNeeded(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	session, err := th.App.CreateSession(&model.Session{UserId: th.BasicUser.Id})
+	require.Nil(t, err)
+	session2, err := th.App.CreateSession(&model.Session{UserId: th.BasicUser2.Id})
+	require.Nil(t, err)
+
+	old := session.LastActivityAt - model.SESSION_ACTIVITY_TIMEOUT - 1
+	<-th.App.Srv.Store.Session().UpdateLastActivityAt(session.Id, old)
+	session.LastActivityAt = old
+	session2.LastActivityAt = old
+
+	th.App.UpdateLastActivityAtIfNeeded(*session)
+	th.App.UpdateLastActivityAtIfNeeded(*session2)
+
+	cached, err := th.App.GetSession(session.Token)
+	require.Nil(t, err)
+	assert.True(t, cached.LastActivityAt > old, "should update the cached session straight away")
+
+	stored, err := th.App.GetSessionById(session.Id)
+	require.Nil(t, err)
+	assert.Equal(t, old, stored.LastActivityAt, "shouldn't write to the database until the activity is flushed")
+
+	// Recent activity isn't recorded again
+	lastActivityAt := cached.LastActivityAt
+	th.App.UpdateLastActivityAtIfNeeded(*cached)
+
+	th.App.FlushSessionActivity()
+
+	stored, err = th.App.GetSessionById(session.Id)
+	require.Nil(t, err)
+	assert.Equal(t, lastActivityAt, stored.LastActivityAt)
+
+	stored, err = th.App.GetSessionById(session2.Id)
+	require.Nil(t, err)
+	assert.True(t, stored.LastActivityAt > old)
+
+	user, err := th.App.GetUser(th.BasicUser.Id)
+	require.Nil(t, err)
+	assert.Equal(t, lastActivityAt, user.LastSeenAt)
+
+	assert.Equal(t, int64(0), th.App.getQueuedSessionActivity(session.Id), "should've emptied the queue")
+}
+
+func TestCreateImpersonationSession(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	adminSession, err := th.App.CreateSession(&model.Session{UserId: th.BasicUser.Id})
+	require.Nil(t, err)
+
+	_, err = th.App.CreateImpersonationSession(adminSession, th.BasicUser2.Id)
+	require.NotNil(t, err)
+	assert.Equal(t, "app.session.impersonate.disabled.app_error", err.Id)
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.ServiceSettings.EnableUserImpersonation = true
+		*cfg.ServiceSettings.ImpersonationSessionLengthInMinutes = 30
+	})
+
+	_, err = th.App.CreateImpersonationSession(adminSession, th.BasicUser.Id)
+	require.NotNil(t, err)
+	assert.Equal(t, "app.session.impersonate.self.app_error", err.Id)
+
+	session, err := th.App.CreateImpersonationSession(adminSession, th.BasicUser2.Id)
+	require.Nil(t, err)
+	assert.True(t, session.IsImpersonation)
+	assert.Equal(t, th.BasicUser2.Id, session.UserId)
+	assert.Equal(t, th.BasicUser.Id, session.Props[model.SESSION_PROP_IMPERSONATOR_ID])
+	assert.Equal(t, adminSession.Id, session.Props[model.SESSION_PROP_IMPERSONATOR_SESSION_ID])
+	assert.Equal(t, int64(30*60*1000), session.ExpiresAt-session.CreateAt, "should be limited to the configured length")
+
+	_, err = th.App.CreateImpersonationSession(session, th.BasicUser.Id)
+	require.NotNil(t, err)
+	assert.Equal(t, "app.session.impersonate.nested.app_error", err.Id)
+
+	t.Run("activity isn't recorded", func(t *testing.T) {
+		session.LastActivityAt = 0
+		th.App.UpdateLastActivityAtIfNeeded(*session)
+
+		stored, err := th.App.GetSessionById(session.Id)
+		require.Nil(t, err)
+		assert.Equal(t, session.CreateAt, stored.LastActivityAt)
+	})
+
+	t.Run("revoked with the admin's session", func(t *testing.T) {
+		_, err := th.App.GetSession(session.Token)
+		require.Nil(t, err)
+
+		require.Nil(t, th.App.RevokeSession(adminSession))
+
+		_, err = th.App.GetSession(session.Token)
+		assert.NotNil(t, err)
+	})
+}
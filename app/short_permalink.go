@@ -0,0 +1,180 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+	throttled "gopkg.in/throttled/throttled.v2"
+	"gopkg.in/throttled/throttled.v2/store/memstore"
+)
+
+const (
+	SHORT_PERMALINK_CLEANUP_TASK_NAME = "Delete Expired Short Permalinks"
+	SHORT_PERMALINK_CLEANUP_INTERVAL  = time.Hour
+
+	// Following a short permalink is limited per user and per address, so that codes can't be found by trying them
+	// until one works
+	SHORT_PERMALINK_LOOKUPS_PER_MINUTE      = 30
+	SHORT_PERMALINK_LOOKUP_BURST            = 10
+	SHORT_PERMALINK_LOOKUP_LIMITER_MAX_KEYS = 10000
+)
+
+func (a *App) startShortPermalinks() error {
+	store, err := memstore.New(SHORT_PERMALINK_LOOKUP_LIMITER_MAX_KEYS)
+	if err != nil {
+		return err
+	}
+
+	limiter, err := throttled.NewGCRARateLimiter(store, throttled.RateQuota{
+		MaxRate:  throttled.PerMin(SHORT_PERMALINK_LOOKUPS_PER_MINUTE),
+		MaxBurst: SHORT_PERMALINK_LOOKUP_BURST,
+	})
+	if err != nil {
+		return err
+	}
+
+	a.shortPermalinkLimiter = limiter
+	a.shortPermalinkCleanupTask = model.CreateRecurringTask(SHORT_PERMALINK_CLEANUP_TASK_NAME, a.DeleteExpiredShortPermalinks, SHORT_PERMALINK_CLEANUP_INTERVAL)
+
+	return nil
+}
+
+func (a *App) stopShortPermalinks() {
+	if a.shortPermalinkCleanupTask != nil {
+		a.shortPermalinkCleanupTask.Cancel()
+		a.shortPermalinkCleanupTask = nil
+	}
+}
+
+func (a *App) DeleteExpiredShortPermalinks() {
+	result := <-a.Srv.Store.ShortPermalink().PermanentDeleteExpired(model.GetMillis())
+	if result.Err != nil {
+		mlog.Warn(fmt.Sprintf("Failed to delete expired short permalinks: %v", result.Err.Error()))
+	} else if count := result.Data.(int64); count > 0 {
+		mlog.Debug(fmt.Sprintf("Deleted %v expired short permalinks", count))
+	}
+}
+
+// GetShortPermalink returns a short link to the post that opens it on the team. A link that was already made for the
+// post is reused as long as it has at least half of its lifetime left, so that notifications about the same post
+// don't each make a new one.
+func (a *App) GetShortPermalink(post *model.Post, teamId string) (string, *model.AppError) {
+	now := model.GetMillis()
+	lifetime := int64(*a.Config().ServiceSettings.ShortPermalinkExpiryDays) * 24 * int64(time.Hour/time.Millisecond)
+
+	var link *model.ShortPermalink
+	if result := <-a.Srv.Store.ShortPermalink().GetLatestForPost(post.Id, teamId, now+lifetime/2); result.Err == nil {
+		link = result.Data.(*model.ShortPermalink)
+	} else if result.Err.StatusCode != http.StatusNotFound {
+		return "", result.Err
+	} else {
+		result := <-a.Srv.Store.ShortPermalink().Save(&model.ShortPermalink{PostId: post.Id, TeamId: teamId, ExpireAt: now + lifetime})
+		if result.Err != nil {
+			return "", result.Err
+		}
+		link = result.Data.(*model.ShortPermalink)
+	}
+
+	return a.GetSiteURL() + model.SHORT_PERMALINK_PATH + link.Code, nil
+}
+
+// getNotificationPermalink returns the link to a post for a notification email, which is a short one when short
+// permalinks are enabled. teamURL is the link to the team, named teamName, that the post is opened on.
+func (a *App) getNotificationPermalink(post *model.Post, teamName string, teamURL string) string {
+	if *a.Config().ServiceSettings.EnableShortPermalinks {
+		team, err := a.GetTeamByName(teamName)
+		if err == nil {
+			var link string
+			if link, err = a.GetShortPermalink(post, team.Id); err == nil {
+				return link
+			}
+		}
+		mlog.Warn(fmt.Sprintf("Failed to get a short permalink for a notification: %v", err.Error()), mlog.String("post_id", post.Id))
+	}
+
+	return teamURL + "/pl/" + post.Id
+}
+
+// getPushNotificationTeamId returns the team that a push notification for a post in the channel opens it on. Direct and
+// group channels aren't on a team, so they're opened on the first of the user's teams.
+func (a *App) getPushNotificationTeamId(channel *model.Channel, userId string) string {
+	if channel.TeamId != "" {
+		return channel.TeamId
+	}
+
+	teams, err := a.GetTeamsForUser(userId)
+	if err != nil || len(teams) == 0 {
+		return ""
+	}
+
+	return teams[0].Id
+}
+
+// CheckShortPermalinkRateLimit returns an error if the key, such as a user id or an address, has followed too many
+// short permalinks recently.
+func (a *App) CheckShortPermalinkRateLimit(key string) *model.AppError {
+	if a.shortPermalinkLimiter == nil {
+		return nil
+	}
+
+	limited, _, err := a.shortPermalinkLimiter.RateLimit(key, 1)
+	if err != nil {
+		mlog.Error(fmt.Sprintf("Failed to rate limit a short permalink: %v", err.Error()))
+		return nil
+	}
+
+	if limited {
+		return model.NewAppError("CheckShortPermalinkRateLimit", "app.short_permalink.rate_limited.app_error", nil, "key="+key, http.StatusTooManyRequests)
+	}
+
+	return nil
+}
+
+// ResolveShortPermalink returns the permalink that a short code is for, as long as it hasn't expired and the session
+// can read the post on the team that the link opens it on.
+func (a *App) ResolveShortPermalink(code string, session *model.Session) (string, *model.AppError) {
+	if !*a.Config().ServiceSettings.EnableShortPermalinks {
+		return "", model.NewAppError("ResolveShortPermalink", "app.short_permalink.disabled.app_error", nil, "", http.StatusNotImplemented)
+	}
+
+	notFound := model.NewAppError("ResolveShortPermalink", "app.short_permalink.not_found.app_error", nil, "", http.StatusNotFound)
+	if !model.IsValidShortPermalinkCode(code) {
+		return "", notFound
+	}
+
+	result := <-a.Srv.Store.ShortPermalink().Get(code)
+	if result.Err != nil {
+		if result.Err.StatusCode == http.StatusNotFound {
+			return "", notFound
+		}
+		return "", result.Err
+	}
+	link := result.Data.(*model.ShortPermalink)
+
+	if link.IsExpired(model.GetMillis()) {
+		return "", model.NewAppError("ResolveShortPermalink", "app.short_permalink.expired.app_error", nil, "", http.StatusGone)
+	}
+
+	post, err := a.GetSinglePost(link.PostId)
+	if err != nil {
+		return "", notFound
+	}
+
+	if !a.SessionHasPermissionToTeam(*session, link.TeamId, model.PERMISSION_VIEW_TEAM) ||
+		!a.SessionHasPermissionToChannel(*session, post.ChannelId, model.PERMISSION_READ_CHANNEL) {
+		return "", model.NewAppError("ResolveShortPermalink", "app.short_permalink.permissions.app_error", nil, "post_id="+post.Id, http.StatusForbidden)
+	}
+
+	team, err := a.GetTeam(link.TeamId)
+	if err != nil {
+		return "", err
+	}
+
+	return a.GetSiteURL() + "/" + team.Name + "/pl/" + post.Id, nil
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+func createTestShortPermalinkSession(t *testing.T, th *TestHelper, user *model.User) *model.Session {
+	session, err := th.App.CreateSession(&model.Session{UserId: user.Id, Roles: user.GetRawRoles()})
+	require.Nil(t, err)
+
+	// Getting the session again loads the teams that the user is on
+	session, err = th.App.GetSession(session.Token)
+	require.Nil(t, err)
+
+	return session
+}
+
+func TestShortPermalinks(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.ServiceSettings.SiteURL = "http://localhost:8065"
+		*cfg.ServiceSettings.EnableShortPermalinks = true
+	})
+
+	channel := th.createChannel(th.BasicTeam, model.CHANNEL_PRIVATE)
+	post := th.CreatePost(channel)
+
+	// The notification email links to the post with a short link
+	body := th.App.getNotificationEmailBody(th.BasicUser2, post, channel, channel.DisplayName, "sender", th.BasicTeam.Name, "http://localhost:8065/"+th.BasicTeam.Name, model.EMAIL_NOTIFICATION_CONTENTS_FULL, utils.GetUserTranslations("en"))
+	require.Contains(t, body, "http://localhost:8065"+model.SHORT_PERMALINK_PATH)
+	assert.NotContains(t, body, "/pl/"+post.Id)
+
+	link, err := th.App.GetShortPermalink(post, th.BasicTeam.Id)
+	require.Nil(t, err)
+	assert.Contains(t, body, link, "the link should be reused for the same post")
+	code := strings.TrimPrefix(link, "http://localhost:8065"+model.SHORT_PERMALINK_PATH)
+	assert.True(t, model.IsValidShortPermalinkCode(code))
+
+	t.Run("authorized", func(t *testing.T) {
+		permalink, err := th.App.ResolveShortPermalink(code, createTestShortPermalinkSession(t, th, th.BasicUser))
+		require.Nil(t, err)
+		assert.Equal(t, "http://localhost:8065/"+th.BasicTeam.Name+"/pl/"+post.Id, permalink)
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		// BasicUser2 is on the team but not in the private channel
+		_, err := th.App.ResolveShortPermalink(code, createTestShortPermalinkSession(t, th, th.BasicUser2))
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusForbidden, err.StatusCode)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		session := createTestShortPermalinkSession(t, th, th.BasicUser)
+
+		_, err := th.App.ResolveShortPermalink(model.NewRandomString(model.SHORT_PERMALINK_CODE_LENGTH), session)
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusNotFound, err.StatusCode)
+
+		_, err = th.App.ResolveShortPermalink("not a code", session)
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusNotFound, err.StatusCode)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		result := <-th.App.Srv.Store.ShortPermalink().Save(&model.ShortPermalink{PostId: post.Id, TeamId: th.BasicTeam.Id, ExpireAt: model.GetMillis() + 50})
+		require.Nil(t, result.Err)
+		expired := result.Data.(*model.ShortPermalink)
+		time.Sleep(100 * time.Millisecond)
+
+		_, err := th.App.ResolveShortPermalink(expired.Code, createTestShortPermalinkSession(t, th, th.BasicUser))
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusGone, err.StatusCode)
+
+		th.App.DeleteExpiredShortPermalinks()
+		result = <-th.App.Srv.Store.ShortPermalink().Get(expired.Code)
+		assert.NotNil(t, result.Err, "expired links should be deleted")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableShortPermalinks = false })
+		defer th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableShortPermalinks = true })
+
+		_, err := th.App.ResolveShortPermalink(code, createTestShortPermalinkSession(t, th, th.BasicUser))
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusNotImplemented, err.StatusCode)
+
+		assert.Equal(t, "http://localhost:8065/team/pl/"+post.Id, th.App.getNotificationPermalink(post, "team", "http://localhost:8065/team"))
+	})
+}
+
+func TestCheckShortPermalinkRateLimit(t *testing.T) {
+	th := Setup()
+	defer th.TearDown()
+
+	key := "ip:" + model.NewId()
+	limited := false
+	for i := 0; i <= SHORT_PERMALINK_LOOKUP_BURST+1; i++ {
+		if err := th.App.CheckShortPermalinkRateLimit(key); err != nil {
+			assert.Equal(t, http.StatusTooManyRequests, err.StatusCode)
+			limited = true
+			break
+		}
+	}
+	assert.True(t, limited, "lookups past the burst should be limited")
+
+	assert.Nil(t, th.App.CheckShortPermalinkRateLimit("ip:"+model.NewId()), "other keys shouldn't be limited")
+}
@@ -5,6 +5,7 @@ package app
 
 import (
 	"regexp"
+	"strconv"
 
 	"fmt"
 	"strings"
@@ -74,3 +75,88 @@ func replaceUserIds(userStore store.UserStore, text string) string {
 	}
 	return text
 }
+
+// The colors that Slack uses for the attachment color keywords
+var slackAttachmentColors = map[string]string{
+	model.SLACK_ATTACHMENT_COLOR_GOOD:    "#2eb886",
+	model.SLACK_ATTACHMENT_COLOR_WARNING: "#daa038",
+	model.SLACK_ATTACHMENT_COLOR_DANGER:  "#a30200",
+}
+
+// Slack links, such as <http://example.com|Example>, URLs, mentions and channel links are left alone when escaping
+// Markdown so that they still work.
+var slackUnescapedTextRegex = regexp.MustCompile(`<[^<>]+>|[a-zA-Z][a-zA-Z0-9+.\-]*://[^\s<>]+|@[\w.\-]+|~[\w\-]+`)
+
+// Characters that start a block, like a heading or list item, when at the beginning of a line
+var slackBlockMarkerRegex = regexp.MustCompile(`(?m)^([ \t]*)([#>+=-]|\d+[.)])`)
+
+var slackMarkdownEscaper = strings.NewReplacer(
+	"\\", "\\\\",
+	"`", "\\`",
+	"*", "\\*",
+	"_", "\\_",
+	"~", "\\~",
+	"[", "\\[",
+	"]", "\\]",
+)
+
+// parseSlackAttachmentText turns the Slack links in a section of an attachment into Markdown ones, first escaping
+// any Markdown in it if the attachment's mrkdwn_in doesn't include the section.
+func parseSlackAttachmentText(attachment *model.SlackAttachment, section string, text string) string {
+	if !attachment.IsMarkdownIn(section) {
+		text = escapeSlackMarkdown(text)
+	}
+
+	return parseSlackLinksToMarkdown(text)
+}
+
+// escapeSlackMarkdown escapes text so that it's shown as it was written instead of being formatted as Markdown.
+func escapeSlackMarkdown(text string) string {
+	var escaped strings.Builder
+
+	last := 0
+	for _, match := range slackUnescapedTextRegex.FindAllStringIndex(text, -1) {
+		escaped.WriteString(slackMarkdownEscaper.Replace(text[last:match[0]]))
+		escaped.WriteString(text[match[0]:match[1]])
+		last = match[1]
+	}
+	escaped.WriteString(slackMarkdownEscaper.Replace(text[last:]))
+
+	return slackBlockMarkerRegex.ReplaceAllStringFunc(escaped.String(), func(marker string) string {
+		return marker[:len(marker)-1] + "\\" + marker[len(marker)-1:]
+	})
+}
+
+// parseSlackAttachmentColor replaces Slack's color keywords with the colors they stand for and adds the # that
+// Slack allows hex color codes to leave out.
+func parseSlackAttachmentColor(color string) string {
+	if hex, ok := slackAttachmentColors[color]; ok {
+		return hex
+	}
+
+	if color != "" && color[0] != '#' && model.IsValidSlackAttachmentColor(color) {
+		return "#" + color
+	}
+
+	return color
+}
+
+// parseSlackTimestamp converts the ts of an attachment, which Slack allows to be a number or a string with a
+// fractional part such as "1529000000.123", to a whole number of seconds since the epoch so that clients can format
+// it. Timestamps that can't be parsed are removed.
+func parseSlackTimestamp(ts interface{}) interface{} {
+	switch ts := ts.(type) {
+	case int64:
+		return ts
+	case int:
+		return int64(ts)
+	case float64:
+		return int64(ts)
+	case string:
+		if seconds, err := strconv.ParseFloat(strings.TrimSpace(ts), 64); err == nil {
+			return int64(seconds)
+		}
+	}
+
+	return nil
+}
@@ -1,8 +1,13 @@
 package app
 
 import (
+	"io/ioutil"
+	"os"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"github.com/mattermost/mattermost-server/model"
 )
 
@@ -81,3 +86,69 @@ func TestProcessSlackAnnouncement(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestParseSlackAttachment(t *testing.T) {
+	payload, err := os.Open("testdata/slack-attachments.json")
+	require.Nil(t, err)
+	defer payload.Close()
+
+	request, appErr := model.IncomingWebhookRequestFromJson(payload)
+	require.Nil(t, appErr)
+
+	post := &model.Post{}
+	parseSlackAttachment(post, request.Attachments)
+	assert.Equal(t, model.POST_SLACK_ATTACHMENT, post.Type)
+
+	expected, err := ioutil.ReadFile("testdata/slack-attachments-transformed.json")
+	require.Nil(t, err)
+	assert.JSONEq(t, string(expected), model.StringInterfaceToJson(post.Props))
+
+	assert.Nil(t, model.ValidateSlackAttachments(post.Props["attachments"]))
+}
+
+func TestEscapeSlackMarkdown(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Text     string
+		Expected string
+	}{
+		"plain text":     {"nothing to see here", "nothing to see here"},
+		"emphasis":       {"*bold* _italic_ ~~strike~~", `\*bold\* \_italic\_ \~~strike\~\~`},
+		"code":           {"`code`", "\\`code\\`"},
+		"backslashes":    {`a \* b`, `a \\\* b`},
+		"markdown links": {"[text](http://example.com)", `\[text\](http://example.com)`},
+		"slack links":    {"see <http://example.com/a_b|a_b> for *more*", `see <http://example.com/a_b|a_b> for \*more\*`},
+		"urls":           {"http://example.com/a_b_c and a_b", `http://example.com/a_b_c and a\_b`},
+		"headings":       {"# heading\n  ## indented", "\\# heading\n  \\## indented"},
+		"lists":          {"- one\n+ two\n3. three\n4) four", "\\- one\n\\+ two\n3\\. three\n4\\) four"},
+		"quotes":         {"> quote", `\> quote`},
+		"mid line":       {"a - b # c > d 1. e", "a - b # c > d 1. e"},
+		"mentions":       {"@channel and @user_name", "@channel and @user_name"},
+		"channel links":  {"join ~town-square", "join ~town-square"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.Expected, escapeSlackMarkdown(tc.Text))
+		})
+	}
+}
+
+func TestParseSlackAttachmentColor(t *testing.T) {
+	assert.Equal(t, "#2eb886", parseSlackAttachmentColor("good"))
+	assert.Equal(t, "#daa038", parseSlackAttachmentColor("warning"))
+	assert.Equal(t, "#a30200", parseSlackAttachmentColor("danger"))
+	assert.Equal(t, "#439FE0", parseSlackAttachmentColor("#439FE0"))
+	assert.Equal(t, "#439FE0", parseSlackAttachmentColor("439FE0"))
+	assert.Equal(t, "#abc", parseSlackAttachmentColor("abc"))
+	assert.Equal(t, "", parseSlackAttachmentColor(""))
+	assert.Equal(t, "red", parseSlackAttachmentColor("red"))
+}
+
+func TestParseSlackTimestamp(t *testing.T) {
+	assert.Equal(t, int64(123456789), parseSlackTimestamp(float64(123456789)))
+	assert.Equal(t, int64(123456789), parseSlackTimestamp(int64(123456789)))
+	assert.Equal(t, int64(123456789), parseSlackTimestamp(123456789))
+	assert.Equal(t, int64(1529000000), parseSlackTimestamp("1529000000.123456"))
+	assert.Equal(t, int64(1529000000), parseSlackTimestamp(" 1529000000 "))
+	assert.Nil(t, parseSlackTimestamp("yesterday"))
+	assert.Nil(t, parseSlackTimestamp(nil))
+	assert.Nil(t, parseSlackTimestamp(true))
+}
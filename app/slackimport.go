@@ -9,15 +9,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"mime/multipart"
+	"net/http"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"unicode/utf8"
 
-	"net/http"
-
 	"github.com/mattermost/mattermost-server/mlog"
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/utils"
@@ -44,22 +45,56 @@ type SlackUser struct {
 }
 
 type SlackFile struct {
-	Id    string `json:"id"`
-	Title string `json:"title"`
+	Id                 string `json:"id"`
+	Title              string `json:"title"`
+	Name               string `json:"name"`
+	URLPrivate         string `json:"url_private"`
+	URLPrivateDownload string `json:"url_private_download"`
+}
+
+type SlackReaction struct {
+	Name  string   `json:"name"`
+	Users []string `json:"users"`
 }
 
 type SlackPost struct {
-	User        string                   `json:"user"`
-	BotId       string                   `json:"bot_id"`
-	BotUsername string                   `json:"username"`
-	Text        string                   `json:"text"`
-	TimeStamp   string                   `json:"ts"`
-	Type        string                   `json:"type"`
-	SubType     string                   `json:"subtype"`
-	Comment     *SlackComment            `json:"comment"`
-	Upload      bool                     `json:"upload"`
-	File        *SlackFile               `json:"file"`
-	Attachments []*model.SlackAttachment `json:"attachments"`
+	User            string                   `json:"user"`
+	BotId           string                   `json:"bot_id"`
+	BotUsername     string                   `json:"username"`
+	Text            string                   `json:"text"`
+	TimeStamp       string                   `json:"ts"`
+	ThreadTimeStamp string                   `json:"thread_ts"`
+	Type            string                   `json:"type"`
+	SubType         string                   `json:"subtype"`
+	Comment         *SlackComment            `json:"comment"`
+	Upload          bool                     `json:"upload"`
+	File            *SlackFile               `json:"file"`
+	Files           []*SlackFile             `json:"files"`
+	Reactions       []*SlackReaction         `json:"reactions"`
+	Attachments     []*model.SlackAttachment `json:"attachments"`
+}
+
+// IsReply returns whether the post was made in a thread started by another post.
+func (p *SlackPost) IsReply() bool {
+	return p.ThreadTimeStamp != "" && p.ThreadTimeStamp != p.TimeStamp
+}
+
+// The tones that Slack's skin-tone-2 to skin-tone-6 emoji modifiers stand for
+var slackSkinTones = map[string]string{
+	"skin-tone-2": "light",
+	"skin-tone-3": "medium_light",
+	"skin-tone-4": "medium",
+	"skin-tone-5": "medium_dark",
+	"skin-tone-6": "dark",
+}
+
+// Slack emoji that have a different name in Mattermost
+var slackEmojiNames = map[string]string{
+	"thumbsup":               "+1",
+	"thumbsdown":             "-1",
+	"simple_smile":           "slightly_smiling_face",
+	"thinking_face":          "thinking",
+	"face_with_rolling_eyes": "roll_eyes",
 }
 
 var isValidChannelNameCharacters = regexp.MustCompile(`^[a-zA-Z0-9\-_]+$`).MatchString
@@ -77,6 +112,52 @@ func truncateRunes(s string, i int) string {
 	return s
 }
 
+// SlackConvertTimeStampMicros converts a Slack timestamp, like 1469785419.000033, to microseconds so that posts
+// made within the same second can still be put in order.
+func SlackConvertTimeStampMicros(ts string) int64 {
+	parts := strings.SplitN(ts, ".", 2)
+
+	seconds, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	var micros int64
+	if len(parts) == 2 {
+		fraction := (parts[1] + "000000")[:6]
+		if micros, err = strconv.ParseInt(fraction, 10, 64); err != nil {
+			micros = 0
+		}
+	}
+
+	return seconds*1000000 + micros
+}
+
+// SlackConvertEmojiName converts the name of a Slack emoji, which may include a skin tone modifier like
+// thumbsup::skin-tone-2, to the name of the same emoji in Mattermost.
+func SlackConvertEmojiName(name string) string {
+	parts := strings.SplitN(name, "::", 2)
+
+	name = parts[0]
+	if _, ok := model.SystemEmojis[name]; !ok {
+		// Slack separates words in the names of some emoji with hyphens where Mattermost uses underscores
+		name = strings.Replace(strings.TrimPrefix(name, "flag-"), "-", "_", -1)
+	}
+	if converted, ok := slackEmojiNames[name]; ok {
+		name = converted
+	}
+
+	if len(parts) == 2 {
+		if tone, ok := slackSkinTones[parts[1]]; ok {
+			if _, ok := model.SystemEmojis[name+"_"+tone+"_skin_tone"]; ok {
+				return name + "_" + tone + "_skin_tone"
+			}
+		}
+	}
+
+	return name
+}
+
 func SlackConvertTimeStamp(ts string) int64 {
 	timeString := strings.SplitN(ts, ".", 2)[0]
 
@@ -223,84 +304,112 @@ func (a *App) SlackAddBotUser(teamId string, log *bytes.Buffer) *model.User {
 	}
 }
 
-func (a *App) SlackAddPosts(teamId string, channel *model.Channel, posts []SlackPost, users map[string]*model.User, uploads map[string]*zip.File, botUser *model.User) {
+// writeSlackSkipped adds a line to the list of things from the export that weren't imported as they were.
+func writeSlackSkipped(skippedLog *bytes.Buffer, translationId string, channel *model.Channel, sPost SlackPost, params map[string]interface{}) {
+	if params == nil {
+		params = make(map[string]interface{})
+	}
+	params["ChannelName"] = channel.DisplayName
+	params["Timestamp"] = sPost.TimeStamp
+
+	skippedLog.WriteString(utils.T(translationId, params))
+}
+
+func (a *App) SlackAddPosts(teamId string, channel *model.Channel, posts []SlackPost, users map[string]*model.User, uploads map[string]*zip.File, botUser *model.User, skippedLog *bytes.Buffer) {
+	// The ids of the posts that have been imported by their Slack timestamp, which is how replies refer to the
+	// start of their thread
+	postIds := make(map[string]string)
+
 	for _, sPost := range posts {
+		var newPost *model.Post
+		var webhookProps model.StringInterface
+
 		switch {
-		case sPost.Type == "message" && (sPost.SubType == "" || sPost.SubType == "file_share"):
+		case sPost.Type == "message" && (sPost.SubType == "" || sPost.SubType == "file_share" || sPost.SubType == "thread_broadcast"):
 			if sPost.User == "" {
 				mlog.Debug("Slack Import: Unable to import the message as the user field is missing.")
+				writeSlackSkipped(skippedLog, "api.slackimport.slack_add_posts.skipped.no_user", channel, sPost, nil)
 				continue
 			} else if users[sPost.User] == nil {
 				mlog.Debug(fmt.Sprintf("Slack Import: Unable to add the message as the Slack user %v does not exist in Mattermost.", sPost.User))
+				writeSlackSkipped(skippedLog, "api.slackimport.slack_add_posts.skipped.user_not_imported", channel, sPost, map[string]interface{}{"UserId": sPost.User})
 				continue
 			}
-			newPost := model.Post{
+			newPost = &model.Post{
 				UserId:    users[sPost.User].Id,
 				ChannelId: channel.Id,
 				Message:   sPost.Text,
 				CreateAt:  SlackConvertTimeStamp(sPost.TimeStamp),
 			}
-			if sPost.Upload {
-				if fileInfo, ok := a.SlackUploadFile(sPost, uploads, teamId, newPost.ChannelId, newPost.UserId); ok {
+
+			// Older exports only have the one file that was shared by the post
+			files := sPost.Files
+			if len(files) == 0 && sPost.Upload && sPost.File != nil {
+				files = []*SlackFile{sPost.File}
+			}
+			for _, sFile := range files {
+				if fileInfo, ok := a.SlackUploadFile(sPost, sFile, uploads, teamId, newPost.ChannelId, newPost.UserId); ok {
 					newPost.FileIds = append(newPost.FileIds, fileInfo.Id)
-					newPost.Message = sPost.File.Title
+				} else {
+					writeSlackSkipped(skippedLog, "api.slackimport.slack_add_posts.skipped.file", channel, sPost, map[string]interface{}{"FileId": sFile.Id})
 				}
 			}
-			a.OldImportPost(&newPost)
-			for _, fileId := range newPost.FileIds {
-				if result := <-a.Srv.Store.FileInfo().AttachToPost(fileId, newPost.Id); result.Err != nil {
-					mlog.Error(fmt.Sprintf("Slack Import: An error occurred when attaching files to a message, post_id=%s, file_ids=%v, err=%v.", newPost.Id, newPost.FileIds, result.Err))
-				}
+			if sPost.Upload && sPost.File != nil && len(newPost.FileIds) > 0 {
+				newPost.Message = sPost.File.Title
 			}
 
 		case sPost.Type == "message" && sPost.SubType == "file_comment":
 			if sPost.Comment == nil {
 				mlog.Debug("Slack Import: Unable to import the message as it has no comments.")
+				writeSlackSkipped(skippedLog, "api.slackimport.slack_add_posts.skipped.no_comment", channel, sPost, nil)
 				continue
 			} else if sPost.Comment.User == "" {
 				mlog.Debug("Slack Import: Unable to import the message as the user field is missing.")
+				writeSlackSkipped(skippedLog, "api.slackimport.slack_add_posts.skipped.no_user", channel, sPost, nil)
 				continue
 			} else if users[sPost.Comment.User] == nil {
 				mlog.Debug(fmt.Sprintf("Slack Import: Unable to add the message as the Slack user %v does not exist in Mattermost.", sPost.User))
+				writeSlackSkipped(skippedLog, "api.slackimport.slack_add_posts.skipped.user_not_imported", channel, sPost, map[string]interface{}{"UserId": sPost.Comment.User})
 				continue
 			}
-			newPost := model.Post{
+			newPost = &model.Post{
 				UserId:    users[sPost.Comment.User].Id,
 				ChannelId: channel.Id,
 				Message:   sPost.Comment.Comment,
 				CreateAt:  SlackConvertTimeStamp(sPost.TimeStamp),
 			}
-			a.OldImportPost(&newPost)
 		case sPost.Type == "message" && sPost.SubType == "bot_message":
 			if botUser == nil {
 				mlog.Warn("Slack Import: Unable to import the bot message as the bot user does not exist.")
+				writeSlackSkipped(skippedLog, "api.slackimport.slack_add_posts.skipped.no_bot_user", channel, sPost, nil)
 				continue
 			} else if sPost.BotId == "" {
 				mlog.Warn("Slack Import: Unable to import bot message as the BotId field is missing.")
+				writeSlackSkipped(skippedLog, "api.slackimport.slack_add_posts.skipped.no_bot_id", channel, sPost, nil)
 				continue
 			}
 
-			props := make(model.StringInterface)
-			props["override_username"] = sPost.BotUsername
+			webhookProps = make(model.StringInterface)
+			webhookProps["override_username"] = sPost.BotUsername
 			if len(sPost.Attachments) > 0 {
-				props["attachments"] = sPost.Attachments
+				webhookProps["attachments"] = sPost.Attachments
 			}
 
-			post := &model.Post{
+			newPost = &model.Post{
 				UserId:    botUser.Id,
 				ChannelId: channel.Id,
 				CreateAt:  SlackConvertTimeStamp(sPost.TimeStamp),
 				Message:   sPost.Text,
 				Type:      model.POST_SLACK_ATTACHMENT,
 			}
-
-			a.OldImportIncomingWebhookPost(post, props)
 		case sPost.Type == "message" && (sPost.SubType == "channel_join" || sPost.SubType == "channel_leave"):
 			if sPost.User == "" {
 				mlog.Debug("Slack Import: Unable to import the message as the user field is missing.")
+				writeSlackSkipped(skippedLog, "api.slackimport.slack_add_posts.skipped.no_user", channel, sPost, nil)
 				continue
 			} else if users[sPost.User] == nil {
 				mlog.Debug(fmt.Sprintf("Slack Import: Unable to add the message as the Slack user %v does not exist in Mattermost.", sPost.User))
+				writeSlackSkipped(skippedLog, "api.slackimport.slack_add_posts.skipped.user_not_imported", channel, sPost, map[string]interface{}{"UserId": sPost.User})
 				continue
 			}
 
@@ -311,7 +420,7 @@ func (a *App) SlackAddPosts(teamId string, channel *model.Channel, posts []Slack
 				postType = model.POST_LEAVE_CHANNEL
 			}
 
-			newPost := model.Post{
+			newPost = &model.Post{
 				UserId:    users[sPost.User].Id,
 				ChannelId: channel.Id,
 				Message:   sPost.Text,
@@ -321,102 +430,189 @@ func (a *App) SlackAddPosts(teamId string, channel *model.Channel, posts []Slack
 					"username": users[sPost.User].Username,
 				},
 			}
-			a.OldImportPost(&newPost)
 		case sPost.Type == "message" && sPost.SubType == "me_message":
 			if sPost.User == "" {
 				mlog.Debug("Slack Import: Unable to import the message as the user field is missing.")
+				writeSlackSkipped(skippedLog, "api.slackimport.slack_add_posts.skipped.no_user", channel, sPost, nil)
 				continue
 			} else if users[sPost.User] == nil {
 				mlog.Debug(fmt.Sprintf("Slack Import: Unable to add the message as the Slack user %v does not exist in Mattermost.", sPost.User))
+				writeSlackSkipped(skippedLog, "api.slackimport.slack_add_posts.skipped.user_not_imported", channel, sPost, map[string]interface{}{"UserId": sPost.User})
 				continue
 			}
-			newPost := model.Post{
+			newPost = &model.Post{
 				UserId:    users[sPost.User].Id,
 				ChannelId: channel.Id,
 				Message:   "*" + sPost.Text + "*",
 				CreateAt:  SlackConvertTimeStamp(sPost.TimeStamp),
 			}
-			a.OldImportPost(&newPost)
-		case sPost.Type == "message" && sPost.SubType == "channel_topic":
+		case sPost.Type == "message" && (sPost.SubType == "channel_topic" || sPost.SubType == "channel_purpose" || sPost.SubType == "channel_name"):
 			if sPost.User == "" {
 				mlog.Debug("Slack Import: Unable to import the message as the user field is missing.")
+				writeSlackSkipped(skippedLog, "api.slackimport.slack_add_posts.skipped.no_user", channel, sPost, nil)
 				continue
 			} else if users[sPost.User] == nil {
 				mlog.Debug(fmt.Sprintf("Slack Import: Unable to add the message as the Slack user %v does not exist in Mattermost.", sPost.User))
+				writeSlackSkipped(skippedLog, "api.slackimport.slack_add_posts.skipped.user_not_imported", channel, sPost, map[string]interface{}{"UserId": sPost.User})
 				continue
 			}
-			newPost := model.Post{
-				UserId:    users[sPost.User].Id,
-				ChannelId: channel.Id,
-				Message:   sPost.Text,
-				CreateAt:  SlackConvertTimeStamp(sPost.TimeStamp),
-				Type:      model.POST_HEADER_CHANGE,
-			}
-			a.OldImportPost(&newPost)
-		case sPost.Type == "message" && sPost.SubType == "channel_purpose":
-			if sPost.User == "" {
-				mlog.Debug("Slack Import: Unable to import the message as the user field is missing.")
-				continue
-			} else if users[sPost.User] == nil {
-				mlog.Debug(fmt.Sprintf("Slack Import: Unable to add the message as the Slack user %v does not exist in Mattermost.", sPost.User))
-				continue
-			}
-			newPost := model.Post{
-				UserId:    users[sPost.User].Id,
-				ChannelId: channel.Id,
-				Message:   sPost.Text,
-				CreateAt:  SlackConvertTimeStamp(sPost.TimeStamp),
-				Type:      model.POST_PURPOSE_CHANGE,
-			}
-			a.OldImportPost(&newPost)
-		case sPost.Type == "message" && sPost.SubType == "channel_name":
-			if sPost.User == "" {
-				mlog.Debug("Slack Import: Unable to import the message as the user field is missing.")
-				continue
-			} else if users[sPost.User] == nil {
-				mlog.Debug(fmt.Sprintf("Slack Import: Unable to add the message as the Slack user %v does not exist in Mattermost.", sPost.User))
-				continue
+
+			var postType string
+			switch sPost.SubType {
+			case "channel_topic":
+				postType = model.POST_HEADER_CHANGE
+			case "channel_purpose":
+				postType = model.POST_PURPOSE_CHANGE
+			default:
+				postType = model.POST_DISPLAYNAME_CHANGE
 			}
-			newPost := model.Post{
+
+			newPost = &model.Post{
 				UserId:    users[sPost.User].Id,
 				ChannelId: channel.Id,
 				Message:   sPost.Text,
 				CreateAt:  SlackConvertTimeStamp(sPost.TimeStamp),
-				Type:      model.POST_DISPLAYNAME_CHANGE,
+				Type:      postType,
 			}
-			a.OldImportPost(&newPost)
 		default:
 			mlog.Warn(fmt.Sprintf("Slack Import: Unable to import the message as its type is not supported: post_type=%v, post_subtype=%v.", sPost.Type, sPost.SubType))
+			writeSlackSkipped(skippedLog, "api.slackimport.slack_add_posts.skipped.unsupported", channel, sPost, map[string]interface{}{"Type": sPost.Type, "SubType": sPost.SubType})
+			continue
+		}
+
+		if sPost.IsReply() {
+			if rootId, ok := postIds[sPost.ThreadTimeStamp]; ok {
+				newPost.RootId = rootId
+				newPost.ParentId = rootId
+			} else {
+				writeSlackSkipped(skippedLog, "api.slackimport.slack_add_posts.skipped.thread", channel, sPost, map[string]interface{}{"ThreadTimestamp": sPost.ThreadTimeStamp})
+			}
+		}
+
+		var postId string
+		if webhookProps != nil {
+			postId = a.OldImportIncomingWebhookPost(newPost, webhookProps)
+		} else {
+			postId = a.OldImportPost(newPost)
 		}
+
+		if postId == "" {
+			writeSlackSkipped(skippedLog, "api.slackimport.slack_add_posts.skipped.save_failed", channel, sPost, nil)
+			continue
+		}
+
+		if !sPost.IsReply() {
+			postIds[sPost.TimeStamp] = postId
+		}
+
+		a.slackAddReactions(postId, SlackConvertTimeStamp(sPost.TimeStamp), sPost, channel, users, skippedLog)
 	}
 }
 
-func (a *App) SlackUploadFile(sPost SlackPost, uploads map[string]*zip.File, teamId string, channelId string, userId string) (*model.FileInfo, bool) {
-	if sPost.File != nil {
-		if file, ok := uploads[sPost.File.Id]; ok {
-			openFile, err := file.Open()
-			if err != nil {
-				mlog.Warn("Slack Import: Unable to open the file {{.FileId}} from the Slack export: {{.Error}}.")
-				return nil, false
+// slackAddReactions adds the reactions to a post that's been imported, converting their emoji names to the ones
+// used by Mattermost.
+func (a *App) slackAddReactions(postId string, createAt int64, sPost SlackPost, channel *model.Channel, users map[string]*model.User, skippedLog *bytes.Buffer) {
+	for _, sReaction := range sPost.Reactions {
+		emojiName := SlackConvertEmojiName(sReaction.Name)
+		if _, ok := model.SystemEmojis[emojiName]; !ok {
+			if result := <-a.Srv.Store.Emoji().GetByName(emojiName); result.Err != nil {
+				writeSlackSkipped(skippedLog, "api.slackimport.slack_add_posts.skipped.reaction_emoji", channel, sPost, map[string]interface{}{"EmojiName": sReaction.Name})
+				continue
 			}
-			defer openFile.Close()
+		}
 
-			timestamp := utils.TimeFromMillis(SlackConvertTimeStamp(sPost.TimeStamp))
-			uploadedFile, err := a.OldImportFile(timestamp, openFile, teamId, channelId, userId, filepath.Base(file.Name))
-			if err != nil {
-				mlog.Warn("Slack Import: An error occurred when uploading file {{.FileId}}: {{.Error}}.")
-				return nil, false
+		for _, userId := range sReaction.Users {
+			user, ok := users[userId]
+			if !ok {
+				writeSlackSkipped(skippedLog, "api.slackimport.slack_add_posts.skipped.reaction_user", channel, sPost, map[string]interface{}{"EmojiName": sReaction.Name, "UserId": userId})
+				continue
 			}
 
-			return uploadedFile, true
-		} else {
-			mlog.Warn("Slack Import: Unable to import file {{.FileId}} as the file is missing from the Slack export zip file.")
+			reaction := &model.Reaction{
+				UserId:    user.Id,
+				PostId:    postId,
+				EmojiName: emojiName,
+				CreateAt:  createAt,
+			}
+			if result := <-a.Srv.Store.Reaction().Save(reaction); result.Err != nil {
+				mlog.Warn(fmt.Sprintf("Slack Import: Unable to save a reaction: %v", result.Err.Error()), mlog.String("post_id", postId))
+				writeSlackSkipped(skippedLog, "api.slackimport.slack_add_posts.skipped.reaction_user", channel, sPost, map[string]interface{}{"EmojiName": sReaction.Name, "UserId": userId})
+			}
+		}
+	}
+}
+
+// SlackUploadFile imports a file that was shared in a post. It's taken from the __uploads directory of the export
+// if it's there. Otherwise, it's downloaded from Slack.
+func (a *App) SlackUploadFile(sPost SlackPost, sFile *SlackFile, uploads map[string]*zip.File, teamId string, channelId string, userId string) (*model.FileInfo, bool) {
+	timestamp := utils.TimeFromMillis(SlackConvertTimeStamp(sPost.TimeStamp))
+
+	if file, ok := uploads[sFile.Id]; ok {
+		openFile, err := file.Open()
+		if err != nil {
+			mlog.Warn(fmt.Sprintf("Slack Import: Unable to open the file %v from the Slack export: %v.", sFile.Id, err.Error()))
 			return nil, false
 		}
-	} else {
-		mlog.Warn("Slack Import: Unable to attach the file to the post as the latter has no file section present in Slack export.")
+		defer openFile.Close()
+
+		uploadedFile, err := a.OldImportFile(timestamp, openFile, teamId, channelId, userId, filepath.Base(file.Name))
+		if err != nil {
+			mlog.Warn(fmt.Sprintf("Slack Import: An error occurred when uploading file %v: %v.", sFile.Id, err.Error()))
+			return nil, false
+		}
+
+		return uploadedFile, true
+	}
+
+	url := sFile.URLPrivateDownload
+	if url == "" {
+		url = sFile.URLPrivate
+	}
+	if url == "" {
+		mlog.Warn(fmt.Sprintf("Slack Import: Unable to import file %v as the file is missing from the Slack export zip file.", sFile.Id))
 		return nil, false
 	}
+
+	data, err := a.slackDownloadFile(url)
+	if err != nil {
+		mlog.Warn(fmt.Sprintf("Slack Import: Unable to download file %v: %v.", sFile.Id, err.Error()))
+		return nil, false
+	}
+
+	name := sFile.Name
+	if name == "" {
+		name = sFile.Id
+	}
+
+	uploadedFile, err := a.OldImportFile(timestamp, bytes.NewReader(data), teamId, channelId, userId, filepath.Base(name))
+	if err != nil {
+		mlog.Warn(fmt.Sprintf("Slack Import: An error occurred when uploading file %v: %v.", sFile.Id, err.Error()))
+		return nil, false
+	}
+
+	return uploadedFile, true
+}
+
+func (a *App) slackDownloadFile(url string) ([]byte, error) {
+	resp, err := a.HTTPClient(false).Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer consumeAndClose(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %v", resp.StatusCode)
+	}
+
+	maxFileSize := *a.Config().FileSettings.MaxFileSize
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxFileSize+1))
+	if err != nil {
+		return nil, err
+	} else if int64(len(data)) > maxFileSize {
+		return nil, fmt.Errorf("larger than the maximum file size of %v bytes", maxFileSize)
+	}
+
+	return data, nil
 }
 
 func (a *App) deactivateSlackBotUser(user *model.User) {
@@ -462,7 +658,7 @@ func SlackSanitiseChannelProperties(channel model.Channel) model.Channel {
 	return channel
 }
 
-func (a *App) SlackAddChannels(teamId string, slackchannels []SlackChannel, posts map[string][]SlackPost, users map[string]*model.User, uploads map[string]*zip.File, botUser *model.User, importerLog *bytes.Buffer) map[string]*model.Channel {
+func (a *App) SlackAddChannels(teamId string, slackchannels []SlackChannel, posts map[string][]SlackPost, users map[string]*model.User, uploads map[string]*zip.File, botUser *model.User, importerLog *bytes.Buffer, skippedLog *bytes.Buffer) map[string]*model.Channel {
 	// Write Header
 	importerLog.WriteString(utils.T("api.slackimport.slack_add_channels.added"))
 	importerLog.WriteString("=================\r\n\r\n")
@@ -503,34 +699,61 @@ func (a *App) SlackAddChannels(teamId string, slackchannels []SlackChannel, post
 		a.addSlackUsersToChannel(sChannel.Members, users, mChannel, importerLog)
 		importerLog.WriteString(newChannel.DisplayName + "\r\n")
 		addedChannels[sChannel.Id] = mChannel
-		a.SlackAddPosts(teamId, mChannel, posts[sChannel.Name], users, uploads, botUser)
+		a.SlackAddPosts(teamId, mChannel, posts[sChannel.Name], users, uploads, botUser, skippedLog)
 	}
 
 	return addedChannels
 }
 
-func SlackConvertUserMentions(users []SlackUser, posts map[string][]SlackPost) map[string][]SlackPost {
-	var regexes = make(map[string]*regexp.Regexp, len(users))
-	for _, user := range users {
-		r, err := regexp.Compile("<@" + user.Id + `(\|` + user.Username + ")?>")
-		if err != nil {
-			mlog.Warn(fmt.Sprint("Slack Import: Unable to compile the @mention, matching regular expression for the Slack user {{.Username}} (id={{.UserID}}).", user.Id, user.Username), mlog.String("user_id", user.Id))
-			continue
-		}
-		regexes["@"+user.Username] = r
-	}
+// SlackConvertUserMentions replaces the mentions of Slack users in the posts with the usernames of the Mattermost
+// users that they were imported as. Mentions of users that weren't imported are left as they are.
+func SlackConvertUserMentions(users map[string]*model.User, posts map[string][]SlackPost) map[string][]SlackPost {
+	userMentionRegex := regexp.MustCompile(`<@([^|>]+)(\|[^>]*)?>`)
+
+	var regexes = make(map[string]*regexp.Regexp)
 
 	// Special cases.
 	regexes["@here"], _ = regexp.Compile(`<!here\|@here>`)
 	regexes["@channel"], _ = regexp.Compile("<!channel>")
 	regexes["@all"], _ = regexp.Compile("<!everyone>")
 
+	convert := func(text string) string {
+		text = userMentionRegex.ReplaceAllStringFunc(text, func(mention string) string {
+			if user, ok := users[userMentionRegex.FindStringSubmatch(mention)[1]]; ok {
+				return "@" + user.Username
+			}
+			return mention
+		})
+
+		for mention, r := range regexes {
+			text = r.ReplaceAllString(text, mention)
+		}
+
+		return text
+	}
+
 	for channelName, channelPosts := range posts {
 		for postIdx, post := range channelPosts {
-			for mention, r := range regexes {
-				post.Text = r.ReplaceAllString(post.Text, mention)
-				posts[channelName][postIdx] = post
+			post.Text = convert(post.Text)
+
+			for _, attachment := range post.Attachments {
+				if attachment == nil {
+					continue
+				}
+
+				attachment.Pretext = convert(attachment.Pretext)
+				attachment.Text = convert(attachment.Text)
+				for _, field := range attachment.Fields {
+					if field == nil {
+						continue
+					}
+					if value, ok := field.Value.(string); ok {
+						field.Value = convert(value)
+					}
+				}
 			}
+
+			posts[channelName][postIdx] = post
 		}
 	}
 
@@ -664,14 +887,23 @@ func (a *App) SlackImport(fileData multipart.File, fileSize int64, teamID string
 		}
 	}
 
-	posts = SlackConvertUserMentions(users, posts)
-	posts = SlackConvertChannelMentions(channels, posts)
-	posts = SlackConvertPostsMarkup(posts)
+	// Each channel's posts are spread across a file per day, and replies need to be imported after the start of their
+	// thread
+	for _, channelPosts := range posts {
+		sort.SliceStable(channelPosts, func(i, j int) bool {
+			return SlackConvertTimeStampMicros(channelPosts[i].TimeStamp) < SlackConvertTimeStampMicros(channelPosts[j].TimeStamp)
+		})
+	}
 
 	addedUsers := a.SlackAddUsers(teamID, users, log)
 	botUser := a.SlackAddBotUser(teamID, log)
 
-	a.SlackAddChannels(teamID, channels, posts, addedUsers, uploads, botUser, log)
+	posts = SlackConvertUserMentions(addedUsers, posts)
+	posts = SlackConvertChannelMentions(channels, posts)
+	posts = SlackConvertPostsMarkup(posts)
+
+	skippedLog := new(bytes.Buffer)
+	a.SlackAddChannels(teamID, channels, posts, addedUsers, uploads, botUser, log, skippedLog)
 
 	if botUser != nil {
 		a.deactivateSlackBotUser(botUser)
@@ -686,5 +918,14 @@ func (a *App) SlackImport(fileData multipart.File, fileSize int64, teamID string
 	log.WriteString(utils.T("api.slackimport.slack_import.note2"))
 	log.WriteString(utils.T("api.slackimport.slack_import.note3"))
 
+	log.WriteString(utils.T("api.slackimport.slack_import.skipped"))
+	log.WriteString("========\r\n\r\n")
+
+	if skippedLog.Len() > 0 {
+		log.Write(skippedLog.Bytes())
+	} else {
+		log.WriteString(utils.T("api.slackimport.slack_import.nothing_skipped"))
+	}
+
 	return nil, log
 }
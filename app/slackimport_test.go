@@ -4,6 +4,8 @@
 package app
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
@@ -36,9 +38,9 @@ func TestSlackConvertChannelName(t *testing.T) {
 }
 
 func TestSlackConvertUserMentions(t *testing.T) {
-	users := []SlackUser{
-		{Id: "U00000A0A", Username: "firstuser"},
-		{Id: "U00000B1B", Username: "seconduser"},
+	users := map[string]*model.User{
+		"U00000A0A": {Username: "firstuser"},
+		"U00000B1B": {Username: "mattermostuser"},
 	}
 
 	posts := map[string][]SlackPost{
@@ -55,6 +57,15 @@ func TestSlackConvertUserMentions(t *testing.T) {
 			{
 				Text: "Regular user test <@U00000B1B|seconduser> and <@U00000A0A>.",
 			},
+			{
+				Text: "Not imported <@U00000C2C|thirduser>.",
+				Attachments: []*model.SlackAttachment{
+					{
+						Pretext: "Hey <@U00000A0A>",
+						Fields:  []*model.SlackAttachmentField{{Value: "<@U00000B1B>"}, nil},
+					},
+				},
+			},
 		},
 	}
 
@@ -70,7 +81,16 @@ func TestSlackConvertUserMentions(t *testing.T) {
 				Text: "Yo @all.",
 			},
 			{
-				Text: "Regular user test @seconduser and @firstuser.",
+				Text: "Regular user test @mattermostuser and @firstuser.",
+			},
+			{
+				Text: "Not imported <@U00000C2C|thirduser>.",
+				Attachments: []*model.SlackAttachment{
+					{
+						Pretext: "Hey @firstuser",
+						Fields:  []*model.SlackAttachmentField{{Value: "@mattermostuser"}, nil},
+					},
+				},
 			},
 		},
 	}
@@ -155,16 +175,16 @@ func TestSlackSanitiseChannelProperties(t *testing.T) {
 	c2 := model.Channel{
 		DisplayName: strings.Repeat("abcdefghij", 7),
 		Name:        strings.Repeat("abcdefghij", 7),
-		Purpose:     strings.Repeat("0123456789", 30),
-		Header:      strings.Repeat("0123456789", 120),
+		Purpose:     strings.Repeat("0123456789", 110),
+		Header:      strings.Repeat("0123456789", 420),
 	}
 
 	c2s := SlackSanitiseChannelProperties(c2)
 	assert.Equal(t, model.Channel{
 		DisplayName: strings.Repeat("abcdefghij", 6) + "abcd",
 		Name:        strings.Repeat("abcdefghij", 6) + "abcd",
-		Purpose:     strings.Repeat("0123456789", 25),
-		Header:      strings.Repeat("0123456789", 102) + "0123",
+		Purpose:     strings.Repeat("0123456789", 102) + "0123",
+		Header:      strings.Repeat("0123456789", 409) + "012345",
 	}, c2s)
 }
 
@@ -271,3 +291,153 @@ in this~.`,
 
 	assert.Equal(t, expectedOutput, SlackConvertPostsMarkup(input))
 }
+
+func TestSlackConvertTimeStampMicros(t *testing.T) {
+	assert.EqualValues(t, 1469785419000033, SlackConvertTimeStampMicros("1469785419.000033"))
+	assert.True(t, SlackConvertTimeStampMicros("1469785419.000033") > SlackConvertTimeStampMicros("1469785419.000032"))
+}
+
+func TestSlackConvertEmojiName(t *testing.T) {
+	for input, expected := range map[string]string{
+		"smile":                 "smile",
+		"thumbsup":              "+1",
+		"thumbsdown":            "-1",
+		"-1":                    "-1",
+		"wave::skin-tone-3":     "wave_medium_light_skin_tone",
+		"thumbsup::skin-tone-6": "+1_dark_skin_tone",
+		"flag-ca":               "ca",
+		"not-an-emoji":          "not_an_emoji",
+	} {
+		assert.Equal(t, expected, SlackConvertEmojiName(input), "input = %v", input)
+	}
+}
+
+func TestSlackImport(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	file, err := os.Open("../tests/slack-import-test-fidelity.zip")
+	require.NoError(t, err)
+	defer file.Close()
+
+	info, err := file.Stat()
+	require.NoError(t, err)
+
+	appErr, log := th.App.SlackImport(file, info.Size(), th.BasicTeam.Id)
+	require.Nil(t, appErr)
+
+	channel, appErr := th.App.GetChannelByName("fidelity", th.BasicTeam.Id)
+	require.Nil(t, appErr)
+
+	alice, appErr := th.App.GetUserByEmail("success+slackalice@simulator.amazonses.com")
+	require.Nil(t, appErr)
+	bob, appErr := th.App.GetUserByEmail("success+slackbob@simulator.amazonses.com")
+	require.Nil(t, appErr)
+
+	postList, appErr := th.App.GetPostsPage(channel.Id, 0, 100)
+	require.Nil(t, appErr)
+
+	posts := make(map[string]*model.Post)
+	for _, post := range postList.Posts {
+		posts[post.Message] = post
+	}
+
+	t.Run("threads", func(t *testing.T) {
+		root := posts["Starting a thread for @"+bob.Username]
+		require.NotNil(t, root)
+		assert.Equal(t, alice.Id, root.UserId)
+		assert.Equal(t, "", root.RootId)
+
+		reply := posts["A reply from @"+bob.Username]
+		require.NotNil(t, reply)
+		assert.Equal(t, root.Id, reply.RootId)
+
+		reply = posts["Another reply"]
+		require.NotNil(t, reply)
+		assert.Equal(t, root.Id, reply.RootId)
+
+		orphan := posts["A reply to a thread that was not exported"]
+		require.NotNil(t, orphan)
+		assert.Equal(t, "", orphan.RootId)
+		assert.Contains(t, log.String(), "1527811300.000300")
+	})
+
+	t.Run("reactions", func(t *testing.T) {
+		root := posts["Starting a thread for @"+bob.Username]
+		require.NotNil(t, root)
+
+		reactions, appErr := th.App.GetReactionsForPost(root.Id)
+		require.Nil(t, appErr)
+
+		emojiNamesByUser := make(map[string]string)
+		for _, reaction := range reactions {
+			emojiNamesByUser[reaction.UserId] = reaction.EmojiName
+			assert.Equal(t, root.CreateAt, reaction.CreateAt)
+		}
+		assert.Equal(t, map[string]string{
+			bob.Id:   "+1",
+			alice.Id: "wave_medium_light_skin_tone",
+		}, emojiNamesByUser)
+
+		assert.Contains(t, log.String(), "U0NOTHERE")
+		assert.Contains(t, log.String(), ":not_a_real_emoji:")
+	})
+
+	t.Run("files", func(t *testing.T) {
+		post := posts["Sharing some files"]
+		require.NotNil(t, post)
+		assert.Equal(t, bob.Id, post.UserId)
+
+		fileInfos, appErr := th.App.GetFileInfosForPost(post.Id, true)
+		require.Nil(t, appErr)
+		require.Len(t, fileInfos, 1)
+		assert.Equal(t, "exported.txt", fileInfos[0].Name)
+
+		assert.Contains(t, log.String(), "F0FIDEL02")
+		assert.Contains(t, log.String(), "F0FIDEL03")
+		assert.NotContains(t, log.String(), "F0FIDEL01")
+	})
+
+	t.Run("mentions", func(t *testing.T) {
+		assert.NotNil(t, posts["A reply from @"+bob.Username])
+		assert.NotContains(t, log.String(), "<@")
+	})
+}
+
+func TestSlackUploadFile(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/files/downloaded.txt" {
+			w.Write([]byte("This file was downloaded."))
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.ServiceSettings.AllowedUntrustedInternalConnections = "localhost 127.0.0.1"
+	})
+
+	sPost := SlackPost{TimeStamp: "1527811200.000100"}
+
+	fileInfo, ok := th.App.SlackUploadFile(sPost, &SlackFile{Id: "F1", Name: "downloaded.txt", URLPrivateDownload: ts.URL + "/files/downloaded.txt"}, nil, th.BasicTeam.Id, th.BasicChannel.Id, th.BasicUser.Id)
+	require.True(t, ok)
+	assert.Equal(t, "downloaded.txt", fileInfo.Name)
+	assert.EqualValues(t, len("This file was downloaded."), fileInfo.Size)
+
+	_, ok = th.App.SlackUploadFile(sPost, &SlackFile{Id: "F2", Name: "missing.txt", URLPrivate: ts.URL + "/files/missing.txt"}, nil, th.BasicTeam.Id, th.BasicChannel.Id, th.BasicUser.Id)
+	assert.False(t, ok)
+
+	_, ok = th.App.SlackUploadFile(sPost, &SlackFile{Id: "F3", Name: "nowhere.txt"}, nil, th.BasicTeam.Id, th.BasicChannel.Id, th.BasicUser.Id)
+	assert.False(t, ok)
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.FileSettings.MaxFileSize = 5
+	})
+
+	_, ok = th.App.SlackUploadFile(sPost, &SlackFile{Id: "F4", Name: "downloaded.txt", URLPrivateDownload: ts.URL + "/files/downloaded.txt"}, nil, th.BasicTeam.Id, th.BasicChannel.Id, th.BasicUser.Id)
+	assert.False(t, ok)
+}
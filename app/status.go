@@ -4,14 +4,15 @@
 package app
 
 import (
-	"fmt"
+	"net/http"
 
-	"github.com/mattermost/mattermost-server/mlog"
 	"github.com/mattermost/mattermost-server/model"
-	"github.com/mattermost/mattermost-server/store"
 	"github.com/mattermost/mattermost-server/utils"
 )
 
+// STATUSES_BY_IDS_BATCH_SIZE is the most statuses that are looked up in the database at once.
+const STATUSES_BY_IDS_BATCH_SIZE = 1000
+
 var statusCache *utils.Cache = utils.NewLru(model.STATUS_CACHE_SIZE)
 
 func ClearStatusCache() {
@@ -81,15 +82,14 @@ func (a *App) GetStatusesByIds(userIds []string) (map[string]interface{}, *model
 	}
 
 	if len(missingUserIds) > 0 {
-		if result := <-a.Srv.Store.Status().GetByIds(missingUserIds); result.Err != nil {
-			return nil, result.Err
-		} else {
-			statuses := result.Data.([]*model.Status)
+		statuses, err := a.getStatusesFromStore(missingUserIds)
+		if err != nil {
+			return nil, err
+		}
 
-			for _, s := range statuses {
-				a.AddStatusCacheSkipClusterSend(s)
-				statusMap[s.UserId] = s.Status
-			}
+		for _, s := range statuses {
+			a.AddStatusCacheSkipClusterSend(s)
+			statusMap[s.UserId] = s.Status
 		}
 	}
 
@@ -128,17 +128,16 @@ func (a *App) GetUserStatusesByIds(userIds []string) ([]*model.Status, *model.Ap
 	}
 
 	if len(missingUserIds) > 0 {
-		if result := <-a.Srv.Store.Status().GetByIds(missingUserIds); result.Err != nil {
-			return nil, result.Err
-		} else {
-			statuses := result.Data.([]*model.Status)
-
-			for _, s := range statuses {
-				a.AddStatusCacheSkipClusterSend(s)
-			}
+		statuses, err := a.getStatusesFromStore(missingUserIds)
+		if err != nil {
+			return nil, err
+		}
 
-			statusMap = append(statusMap, statuses...)
+		for _, s := range statuses {
+			a.AddStatusCacheSkipClusterSend(s)
 		}
+
+		statusMap = append(statusMap, statuses...)
 	}
 
 	// For the case where the user does not have a row in the Status table and cache
@@ -161,6 +160,48 @@ func (a *App) GetUserStatusesByIds(userIds []string) ([]*model.Status, *model.Ap
 	return statusMap, nil
 }
 
+// getStatusesFromStore looks up statuses a batch at a time so that clients asking for the statuses of a lot of users
+// don't make the server run one huge query.
+func (a *App) getStatusesFromStore(userIds []string) ([]*model.Status, *model.AppError) {
+	statuses := []*model.Status{}
+	for start := 0; start < len(userIds); start += STATUSES_BY_IDS_BATCH_SIZE {
+		end := start + STATUSES_BY_IDS_BATCH_SIZE
+		if end > len(userIds) {
+			end = len(userIds)
+		}
+
+		result := <-a.Srv.Store.Status().GetByIds(userIds[start:end])
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		statuses = append(statuses, result.Data.([]*model.Status)...)
+	}
+
+	return statuses, nil
+}
+
+// SubscribeToPresence makes status events for the given users be sent to the connection along with the ones for the
+// connection's own user, and returns the users' current statuses. Connections can't be subscribed to more users
+// than ServiceSettings.MaxPresenceSubscriptionsPerConnection allows.
+func (a *App) SubscribeToPresence(webConn *WebConn, userIds []string) (map[string]interface{}, *model.AppError) {
+	if !*a.Config().ServiceSettings.EnablePresenceSubscriptions {
+		return nil, model.NewAppError("SubscribeToPresence", "app.status.subscribe.disabled.app_error", nil, "", http.StatusNotImplemented)
+	}
+
+	max := *a.Config().ServiceSettings.MaxPresenceSubscriptionsPerConnection
+	if !a.hubRegistry.subscribeToPresence(webConn, userIds, max) {
+		return nil, model.NewAppError("SubscribeToPresence", "app.status.subscribe.too_many.app_error", map[string]interface{}{"Max": max}, "user_id="+webConn.UserId, http.StatusBadRequest)
+	}
+
+	return a.GetStatusesByIds(userIds)
+}
+
+// UnsubscribeFromPresence stops status events for the given users from being sent to the connection, or for every
+// user that it's subscribed to if userIds is empty.
+func (a *App) UnsubscribeFromPresence(webConn *WebConn, userIds []string) {
+	a.hubRegistry.unsubscribeFromPresence(webConn, userIds)
+}
+
 func (a *App) SetStatusOnline(userId string, sessionId string, manual bool) {
 	if !*a.Config().ServiceSettings.EnableUserStatuses {
 		return
@@ -200,17 +241,7 @@ func (a *App) SetStatusOnline(userId string, sessionId string, manual bool) {
 	// Only update the database if the status has changed, the status has been manually set,
 	// or enough time has passed since the previous action
 	if status.Status != oldStatus || status.Manual != oldManual || status.LastActivityAt-oldTime > model.STATUS_MIN_UPDATE_TIME {
-
-		var schan store.StoreChannel
-		if broadcast {
-			schan = a.Srv.Store.Status().SaveOrUpdate(status)
-		} else {
-			schan = a.Srv.Store.Status().UpdateLastActivityAt(status.UserId, status.LastActivityAt)
-		}
-
-		if result := <-schan; result.Err != nil {
-			mlog.Error(fmt.Sprintf("Failed to save status for user_id=%v, err=%v", userId, result.Err), mlog.String("user_id", userId))
-		}
+		a.saveStatus(status, broadcast)
 	}
 
 	if broadcast {
@@ -291,10 +322,7 @@ func (a *App) SetStatusDoNotDisturb(userId string) {
 
 func (a *App) SaveAndBroadcastStatus(status *model.Status) *model.AppError {
 	a.AddStatusCache(status)
-
-	if result := <-a.Srv.Store.Status().SaveOrUpdate(status); result.Err != nil {
-		mlog.Error(fmt.Sprintf("Failed to save status for user_id=%v, err=%v", status.UserId, result.Err))
-	}
+	a.saveStatus(status, true)
 
 	event := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_STATUS_CHANGE, "", "", status.UserId, nil)
 	event.Add("status", status.Status)
@@ -319,10 +347,7 @@ func (a *App) SetStatusOutOfOffice(userId string) {
 	status.Manual = true
 
 	a.AddStatusCache(status)
-
-	if result := <-a.Srv.Store.Status().SaveOrUpdate(status); result.Err != nil {
-		mlog.Error(fmt.Sprintf("Failed to save status for user_id=%v, err=%v", userId, result.Err), mlog.String("user_id", userId))
-	}
+	a.saveStatus(status, true)
 
 	event := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_STATUS_CHANGE, "", "", status.UserId, nil)
 	event.Add("status", model.STATUS_OUT_OF_OFFICE)
@@ -351,6 +376,11 @@ func (a *App) GetStatus(userId string) (*model.Status, *model.AppError) {
 		return status, nil
 	}
 
+	// The status may have been evicted from the cache before it was written
+	if status := a.getPendingStatus(userId); status != nil {
+		return status, nil
+	}
+
 	if result := <-a.Srv.Store.Status().Get(userId); result.Err != nil {
 		return nil, result.Err
 	} else {
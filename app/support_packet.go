@@ -0,0 +1,290 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+// supportPacketFile generates one of the files in a support packet.
+type supportPacketFile struct {
+	name     string
+	generate func() ([]byte, *model.AppError)
+}
+
+// GenerateSupportPacket writes a zip file to w with what's needed to diagnose a problem with the server: its config,
+// the last logMaxBytes of its log, the state of its database, plugins, failed jobs, host and cluster. Every secret in
+// the config is removed from all of it. A file that can't be generated is left out, with why in the manifest, so
+// that one thing being broken doesn't stop the rest from being collected. Only one support packet is generated at a
+// time, since they're meant to be generated by hand and each can read a lot of the log.
+func (a *App) GenerateSupportPacket(w io.Writer, logMaxBytes int64) *model.AppError {
+	if !atomic.CompareAndSwapInt32(&a.generatingSupportPacket, 0, 1) {
+		return model.NewAppError("GenerateSupportPacket", "app.support_packet.in_progress.app_error", nil, "", http.StatusTooManyRequests)
+	}
+	defer atomic.StoreInt32(&a.generatingSupportPacket, 0)
+
+	cfg := a.Config()
+	redactor := model.NewSupportPacketRedactor(cfg)
+
+	hostname, _ := os.Hostname()
+	manifest := &model.SupportPacketManifest{
+		ServerVersion: model.CurrentVersion,
+		BuildNumber:   model.BuildNumber,
+		BuildHash:     model.BuildHash,
+		Enterprise:    model.BuildEnterpriseReady == "true",
+		Hostname:      hostname,
+		GeneratedAt:   model.GetMillis(),
+		Files:         []string{},
+		Errors:        map[string]string{},
+	}
+
+	zipWriter := zip.NewWriter(w)
+
+	writeFile := func(name string, content string) *model.AppError {
+		fileWriter, err := zipWriter.Create(name)
+		if err == nil {
+			_, err = io.WriteString(fileWriter, content)
+		}
+		if err != nil {
+			return model.NewAppError("GenerateSupportPacket", "app.support_packet.write.app_error", nil, err.Error(), http.StatusInternalServerError)
+		}
+
+		return nil
+	}
+
+	files := []supportPacketFile{
+		{model.SUPPORT_PACKET_CONFIG_FILE, a.supportPacketConfig},
+		{model.SUPPORT_PACKET_LOG_FILE, func() ([]byte, *model.AppError) { return a.supportPacketLog(logMaxBytes) }},
+		{model.SUPPORT_PACKET_DATABASE_FILE, a.supportPacketDatabase},
+		{model.SUPPORT_PACKET_PLUGINS_FILE, a.supportPacketPlugins},
+		{model.SUPPORT_PACKET_JOBS_FILE, a.supportPacketJobs},
+		{model.SUPPORT_PACKET_HOST_FILE, a.supportPacketHost},
+		{model.SUPPORT_PACKET_CLUSTER_FILE, a.supportPacketCluster},
+	}
+
+	for _, file := range files {
+		content, err := file.generate()
+		if err != nil {
+			manifest.Errors[file.name] = redactor.Redact(err.Error())
+			continue
+		}
+
+		redacted := redactor.Redact(string(content))
+		if file.name == model.SUPPORT_PACKET_LOG_FILE {
+			redacted = redactor.RedactLog(string(content))
+		}
+
+		if err := writeFile(file.name, redacted); err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, file.name)
+	}
+
+	if err := writeFile(model.SUPPORT_PACKET_MANIFEST_FILE, manifest.ToJson()); err != nil {
+		return err
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return model.NewAppError("GenerateSupportPacket", "app.support_packet.write.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+func supportPacketJson(v interface{}) ([]byte, *model.AppError) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, model.NewAppError("GenerateSupportPacket", "app.support_packet.marshal.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	return b, nil
+}
+
+func (a *App) supportPacketConfig() ([]byte, *model.AppError) {
+	cfg := a.Config().Clone()
+	cfg.SanitizeForSupportPacket()
+
+	return supportPacketJson(cfg)
+}
+
+func (a *App) supportPacketLog(maxBytes int64) ([]byte, *model.AppError) {
+	if !a.Config().LogSettings.EnableFile {
+		return nil, model.NewAppError("GenerateSupportPacket", "app.support_packet.log_file_disabled.app_error", nil, "", http.StatusNotImplemented)
+	}
+
+	content, err := readFileTail(utils.GetLogFileLocation(a.Config().LogSettings.FileLocation), maxBytes)
+	if err != nil {
+		return nil, model.NewAppError("GenerateSupportPacket", "api.admin.file_read_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	return content, nil
+}
+
+// readFileTail returns up to the last maxBytes of the file, starting from the first full line in them.
+func readFileTail(path string, maxBytes int64) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	offset := info.Size() - maxBytes
+	if offset < 0 {
+		offset = 0
+	}
+
+	content := make([]byte, info.Size()-offset)
+	if _, err := io.ReadFull(io.NewSectionReader(file, offset, int64(len(content))), content); err != nil {
+		return nil, err
+	}
+
+	if offset > 0 {
+		if i := bytes.IndexByte(content, '\n'); i >= 0 {
+			content = content[i+1:]
+		} else {
+			content = nil
+		}
+	}
+
+	return content, nil
+}
+
+func (a *App) supportPacketDatabase() ([]byte, *model.AppError) {
+	result := <-a.Srv.Store.System().Get()
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	props := result.Data.(model.StringMap)
+
+	database := &model.SupportPacketDatabase{
+		DriverName:          *a.Config().SqlSettings.DriverName,
+		SchemaVersion:       props["Version"],
+		ServerSchemaVersion: model.CurrentVersion,
+		CompletedMigrations: []string{},
+		PendingMigrations:   []string{},
+		MasterConnections:   a.Srv.Store.TotalMasterDbConnections(),
+		ReadConnections:     a.Srv.Store.TotalReadDbConnections(),
+		SearchConnections:   a.Srv.Store.TotalSearchDbConnections(),
+		MasterHealthy:       a.Srv.Store.IsMasterHealthy(),
+		ReadOnly:            a.Srv.Store.IsReadOnly(),
+	}
+
+	for _, key := range advancedMigrationKeys {
+		if _, ok := props[key]; ok {
+			database.CompletedMigrations = append(database.CompletedMigrations, key)
+		} else {
+			database.PendingMigrations = append(database.PendingMigrations, key)
+		}
+	}
+
+	return supportPacketJson(database)
+}
+
+func (a *App) supportPacketPlugins() ([]byte, *model.AppError) {
+	statuses, err := a.GetClusterPluginStatuses()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].PluginId != statuses[j].PluginId {
+			return statuses[i].PluginId < statuses[j].PluginId
+		}
+		return statuses[i].ClusterId < statuses[j].ClusterId
+	})
+
+	return supportPacketJson(statuses)
+}
+
+func (a *App) supportPacketJobs() ([]byte, *model.AppError) {
+	result := <-a.Srv.Store.Job().GetAllByStatus(model.JOB_STATUS_ERROR)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	since := model.GetMillis() - model.SUPPORT_PACKET_JOB_FAILURES_MAX_AGE
+
+	failed := []*model.Job{}
+	for _, job := range result.Data.([]*model.Job) {
+		if job.LastActivityAt >= since {
+			failed = append(failed, job)
+		}
+	}
+
+	sort.Slice(failed, func(i, j int) bool {
+		return failed[i].LastActivityAt > failed[j].LastActivityAt
+	})
+	if len(failed) > model.SUPPORT_PACKET_MAX_JOB_FAILURES {
+		failed = failed[:model.SUPPORT_PACKET_MAX_JOB_FAILURES]
+	}
+
+	return supportPacketJson(failed)
+}
+
+func (a *App) supportPacketHost() ([]byte, *model.AppError) {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	hostname, _ := os.Hostname()
+
+	return supportPacketJson(&model.SupportPacketHost{
+		Hostname:         hostname,
+		GoVersion:        runtime.Version(),
+		OS:               runtime.GOOS,
+		Arch:             runtime.GOARCH,
+		NumCPU:           runtime.NumCPU(),
+		NumGoroutine:     runtime.NumGoroutine(),
+		TotalMemoryBytes: totalMemoryBytes(),
+		SysMemoryBytes:   memStats.Sys,
+		HeapInUseBytes:   memStats.HeapInuse,
+		NumGC:            memStats.NumGC,
+	})
+}
+
+// totalMemoryBytes returns how much memory the host has, or 0 if it can't tell, which it never can outside of Linux.
+func totalMemoryBytes() uint64 {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 3 && fields[0] == "MemTotal:" && fields[2] == "kB" {
+			kilobytes, _ := strconv.ParseUint(fields[1], 10, 64)
+			return kilobytes * 1024
+		}
+	}
+
+	return 0
+}
+
+func (a *App) supportPacketCluster() ([]byte, *model.AppError) {
+	return supportPacketJson(&model.SupportPacketCluster{
+		Enabled:     *a.Config().ClusterSettings.Enable,
+		ClusterName: *a.Config().ClusterSettings.ClusterName,
+		Nodes:       a.GetClusterStatus(),
+	})
+}
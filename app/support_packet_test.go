@@ -0,0 +1,210 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+func readSupportPacket(t *testing.T, packet []byte) map[string]string {
+	reader, err := zip.NewReader(bytes.NewReader(packet), int64(len(packet)))
+	require.Nil(t, err)
+
+	files := map[string]string{}
+	for _, file := range reader.File {
+		fileReader, err := file.Open()
+		require.Nil(t, err)
+		content, err := ioutil.ReadAll(fileReader)
+		fileReader.Close()
+		require.Nil(t, err)
+
+		files[file.Name] = string(content)
+	}
+
+	return files
+}
+
+func TestGenerateSupportPacket(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	logDir, err := ioutil.TempDir("", "support-packet")
+	require.Nil(t, err)
+	defer os.RemoveAll(logDir)
+
+	secrets := []string{"smtp-password-sentinel", "s3-secret-sentinel", "gitlab-secret-sentinel", "plugin-secret-sentinel"}
+
+	oldCfg := th.App.Config().Clone()
+	defer th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg = *oldCfg
+	})
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		cfg.EmailSettings.SMTPPassword = secrets[0]
+		cfg.FileSettings.AmazonS3SecretAccessKey = secrets[1]
+		cfg.GitLabSettings.Secret = secrets[2]
+		cfg.PluginSettings.Plugins = map[string]interface{}{
+			"testplugin": map[string]interface{}{"apikey": secrets[3]},
+		}
+	})
+
+	secrets = append(secrets, *th.App.Config().SqlSettings.DataSource)
+
+	now := model.GetMillis()
+	recentFailure := store.Must(th.App.Srv.Store.Job().Save(&model.Job{
+		Id:             model.NewId(),
+		Type:           model.JOB_TYPE_DATA_RETENTION,
+		Status:         model.JOB_STATUS_ERROR,
+		CreateAt:       now,
+		LastActivityAt: now,
+		Data:           map[string]string{"error": "failed to connect with " + secrets[1]},
+	})).(*model.Job)
+	oldFailure := store.Must(th.App.Srv.Store.Job().Save(&model.Job{
+		Id:             model.NewId(),
+		Type:           model.JOB_TYPE_DATA_RETENTION,
+		Status:         model.JOB_STATUS_ERROR,
+		CreateAt:       now - 2*model.SUPPORT_PACKET_JOB_FAILURES_MAX_AGE,
+		LastActivityAt: now - 2*model.SUPPORT_PACKET_JOB_FAILURES_MAX_AGE,
+	})).(*model.Job)
+	defer th.App.Srv.Store.Job().Delete(recentFailure.Id)
+	defer th.App.Srv.Store.Job().Delete(oldFailure.Id)
+
+	t.Run("without a log file", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) {
+			cfg.LogSettings.EnableFile = false
+		})
+
+		var packet bytes.Buffer
+		require.Nil(t, th.App.GenerateSupportPacket(&packet, 1024*1024))
+
+		files := readSupportPacket(t, packet.Bytes())
+		manifest := model.SupportPacketManifestFromJson(strings.NewReader(files[model.SUPPORT_PACKET_MANIFEST_FILE]))
+		require.NotNil(t, manifest)
+
+		assert.Equal(t, model.CurrentVersion, manifest.ServerVersion)
+		assert.NotContains(t, manifest.Files, model.SUPPORT_PACKET_LOG_FILE)
+		assert.Contains(t, manifest.Errors, model.SUPPORT_PACKET_LOG_FILE)
+		assert.NotContains(t, files, model.SUPPORT_PACKET_LOG_FILE)
+
+		for _, name := range []string{
+			model.SUPPORT_PACKET_CONFIG_FILE,
+			model.SUPPORT_PACKET_DATABASE_FILE,
+			model.SUPPORT_PACKET_JOBS_FILE,
+			model.SUPPORT_PACKET_HOST_FILE,
+			model.SUPPORT_PACKET_CLUSTER_FILE,
+		} {
+			assert.Contains(t, manifest.Files, name)
+			assert.Contains(t, files, name)
+		}
+
+		var database model.SupportPacketDatabase
+		require.Nil(t, json.Unmarshal([]byte(files[model.SUPPORT_PACKET_DATABASE_FILE]), &database))
+		assert.Equal(t, model.CurrentVersion, database.SchemaVersion)
+		assert.Equal(t, *th.App.Config().SqlSettings.DriverName, database.DriverName)
+		assert.Empty(t, database.PendingMigrations)
+
+		var jobs []*model.Job
+		require.Nil(t, json.Unmarshal([]byte(files[model.SUPPORT_PACKET_JOBS_FILE]), &jobs))
+		var jobIds []string
+		for _, job := range jobs {
+			jobIds = append(jobIds, job.Id)
+		}
+		assert.Contains(t, jobIds, recentFailure.Id)
+		assert.NotContains(t, jobIds, oldFailure.Id)
+
+		var host model.SupportPacketHost
+		require.Nil(t, json.Unmarshal([]byte(files[model.SUPPORT_PACKET_HOST_FILE]), &host))
+		assert.NotEmpty(t, host.GoVersion)
+		assert.NotZero(t, host.NumCPU)
+	})
+
+	t.Run("secrets are redacted everywhere", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) {
+			cfg.LogSettings.EnableFile = true
+			cfg.LogSettings.FileLocation = logDir
+		})
+
+		log := "{\"level\":\"error\",\"msg\":\"Failed to send email\",\"error\":\"auth failed for " + secrets[0] + "\"}\n" +
+			"{\"level\":\"info\",\"msg\":\"Logged in\",\"token\":\"unconfigured-token-value\"}\n"
+		for _, secret := range secrets {
+			log += "{\"level\":\"debug\",\"msg\":\"" + secret + "\"}\n"
+		}
+		require.Nil(t, ioutil.WriteFile(utils.GetLogFileLocation(logDir), []byte(log), 0600))
+
+		var packet bytes.Buffer
+		require.Nil(t, th.App.GenerateSupportPacket(&packet, 1024*1024))
+
+		files := readSupportPacket(t, packet.Bytes())
+		require.Contains(t, files, model.SUPPORT_PACKET_LOG_FILE)
+		assert.Contains(t, files[model.SUPPORT_PACKET_LOG_FILE], "Failed to send email")
+		assert.NotContains(t, files[model.SUPPORT_PACKET_LOG_FILE], "unconfigured-token-value")
+
+		for name, content := range files {
+			for _, secret := range secrets {
+				assert.NotContains(t, content, secret, "%v shouldn't contain a secret", name)
+			}
+		}
+
+		var cfg model.Config
+		require.Nil(t, json.Unmarshal([]byte(files[model.SUPPORT_PACKET_CONFIG_FILE]), &cfg))
+		assert.Equal(t, model.FAKE_SETTING, cfg.EmailSettings.SMTPPassword)
+		assert.Equal(t, model.FAKE_SETTING, *cfg.SqlSettings.DataSource)
+		assert.Equal(t, *th.App.Config().ServiceSettings.SiteURL, *cfg.ServiceSettings.SiteURL)
+	})
+
+	t.Run("one at a time", func(t *testing.T) {
+		th.App.generatingSupportPacket = 1
+		defer func() {
+			th.App.generatingSupportPacket = 0
+		}()
+
+		var packet bytes.Buffer
+		err := th.App.GenerateSupportPacket(&packet, 0)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.support_packet.in_progress.app_error", err.Id)
+		assert.Zero(t, packet.Len())
+	})
+}
+
+func TestReadFileTail(t *testing.T) {
+	dir, err := ioutil.TempDir("", "support-packet")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "mattermost.log")
+	require.Nil(t, ioutil.WriteFile(path, []byte("first line\nsecond line\nthird line\n"), 0600))
+
+	for name, tc := range map[string]struct {
+		maxBytes int64
+		expected string
+	}{
+		"whole file":          {1024, "first line\nsecond line\nthird line\n"},
+		"exactly the file":    {int64(len("first line\nsecond line\nthird line\n")), "first line\nsecond line\nthird line\n"},
+		"starts at full line": {int64(len("ond line\nthird line\n")), "third line\n"},
+		"within a line":       {int64(len("line\n")), ""},
+		"nothing":             {0, ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			content, err := readFileTail(path, tc.maxBytes)
+			require.Nil(t, err)
+			assert.Equal(t, tc.expected, string(content))
+		})
+	}
+
+	_, err = readFileTail(filepath.Join(dir, "missing.log"), 1024)
+	assert.NotNil(t, err)
+}
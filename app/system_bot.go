@@ -0,0 +1,60 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// EnsureSystemBot returns the built-in bot that the server and plugins send messages as, creating it the first time
+// it's needed. The bot can't log in and isn't counted as a user.
+func (a *App) EnsureSystemBot() (*model.User, *model.AppError) {
+	a.systemBotLock.Lock()
+	defer a.systemBotLock.Unlock()
+
+	if result := <-a.Srv.Store.System().GetByName(model.SYSTEM_BOT_USER_ID); result.Err == nil {
+		if result := <-a.Srv.Store.User().Get(result.Data.(*model.System).Value); result.Err == nil {
+			return result.Data.(*model.User), nil
+		}
+
+		// the bot has been deleted, so create a new one
+	}
+
+	username := model.SYSTEM_BOT_USERNAME
+	for i := 1; a.IsUsernameTaken(username); i++ {
+		username = fmt.Sprintf("%v-%v", model.SYSTEM_BOT_USERNAME, i)
+	}
+
+	bot := &model.User{
+		Username:      username,
+		Email:         model.NewId() + "@localhost",
+		EmailVerified: true,
+		FirstName:     "System",
+		LastName:      "Bot",
+		Roles:         model.SYSTEM_USER_ROLE_ID,
+		Locale:        *a.Config().LocalizationSettings.DefaultClientLocale,
+		IsBot:         true,
+	}
+	bot.SetDefaultNotifications()
+	bot.NotifyProps[model.EMAIL_NOTIFY_PROP] = "false"
+	bot.NotifyProps[model.PUSH_NOTIFY_PROP] = model.USER_NOTIFY_NONE
+	bot.NotifyProps[model.DESKTOP_NOTIFY_PROP] = model.USER_NOTIFY_NONE
+
+	if result := <-a.Srv.Store.User().Save(bot); result.Err != nil {
+		return nil, result.Err
+	} else {
+		bot = result.Data.(*model.User)
+	}
+
+	if result := <-a.Srv.Store.System().SaveOrUpdate(&model.System{Name: model.SYSTEM_BOT_USER_ID, Value: bot.Id}); result.Err != nil {
+		return nil, result.Err
+	}
+
+	mlog.Info(fmt.Sprintf("Created the system bot user_id=%v", bot.Id))
+
+	return bot, nil
+}
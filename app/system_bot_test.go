@@ -0,0 +1,48 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestEnsureSystemBot(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	result := <-th.App.Srv.Store.User().GetTotalUsersCount()
+	require.Nil(t, result.Err)
+	userCount := result.Data.(int64)
+
+	bot, err := th.App.EnsureSystemBot()
+	require.Nil(t, err)
+	assert.True(t, bot.IsBot)
+	assert.Equal(t, model.SYSTEM_USER_ROLE_ID, bot.Roles)
+
+	// the same bot is returned once it exists
+	again, err := th.App.EnsureSystemBot()
+	require.Nil(t, err)
+	assert.Equal(t, bot.Id, again.Id)
+
+	// and it isn't counted as a user
+	result = <-th.App.Srv.Store.User().GetTotalUsersCount()
+	require.Nil(t, result.Err)
+	assert.Equal(t, userCount, result.Data.(int64))
+
+	// nor can it log in
+	_, err = th.App.AuthenticateUserForLogin("", bot.Username, "", "", false)
+	assert.NotNil(t, err)
+
+	// users can't make themselves bots
+	user := th.BasicUser
+	user.IsBot = true
+	updated, err := th.App.UpdateUser(user, false)
+	require.Nil(t, err)
+	assert.False(t, updated.IsBot)
+}
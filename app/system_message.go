@@ -0,0 +1,160 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+	"github.com/nicksnyder/go-i18n/i18n"
+)
+
+// systemMessageRenderer renders the text of a system message from its props, returning false if the post doesn't
+// have the props needed to do so.
+type systemMessageRenderer func(post *model.Post, T i18n.TranslateFunc) (string, bool)
+
+// systemMessageRenderers renders system messages by post type so that they can be shown in each user's language
+// instead of the one that the server used when the post was made.
+var systemMessageRenderers = map[string]systemMessageRenderer{
+	model.POST_JOIN_CHANNEL:           renderSystemMessageFromProps("api.channel.join_channel.post_and_forget", "username"),
+	model.POST_LEAVE_CHANNEL:          renderSystemMessageFromProps("api.channel.leave.left", "username"),
+	model.POST_JOIN_TEAM:              renderSystemMessageFromProps("api.team.join_team.post_and_forget", "username"),
+	model.POST_LEAVE_TEAM:             renderSystemMessageFromProps("api.team.leave.left", "username"),
+	model.POST_ADD_TO_CHANNEL:         renderSystemMessageFromProps("api.channel.add_member.added", "addedUsername", "username"),
+	model.POST_ADD_TO_TEAM:            renderSystemMessageFromProps("api.team.add_user_to_team.added", "addedUsername", "username"),
+	model.POST_REMOVE_FROM_CHANNEL:    renderSystemMessageFromProps("api.channel.remove_member.removed", "removedUsername"),
+	model.POST_REMOVE_FROM_TEAM:       renderSystemMessageFromProps("api.team.remove_user_from_team.removed", "username"),
+	model.POST_CHANNEL_DELETED:        renderSystemMessageFromProps("api.channel.delete_channel.archived", "username"),
+	model.POST_CHANNEL_INACTIVE:       renderSystemMessageFromProps("app.channel_archiving.warning", "inactive_days", "warning_days"),
+	model.POST_MOVE_CHANNEL:           renderSystemMessageFromProps("api.team.move_channel.success", "previous_team_name"),
+	model.POST_DISPLAYNAME_CHANGE:     renderSystemMessageFromProps("api.channel.post_update_channel_displayname_message_and_forget.updated_from", "username", "old_displayname", "new_displayname"),
+	model.POST_HEADER_CHANGE:          renderHeaderChangeMessage,
+	model.POST_PURPOSE_CHANGE:         renderPurposeChangeMessage,
+	model.POST_CHANGE_CHANNEL_PRIVACY: renderChannelPrivacyMessage,
+}
+
+// RenderSystemMessage returns the text of post in the language of T. Posts that aren't system messages, and system
+// messages made before their props were enough to render them, keep the text that they were stored with.
+func RenderSystemMessage(post *model.Post, T i18n.TranslateFunc) string {
+	if render, ok := systemMessageRenderers[post.Type]; ok {
+		if message, ok := render(post, T); ok {
+			return message
+		}
+	}
+
+	return post.Message
+}
+
+// systemMessageProps returns the values of the given string props of post in order, or false if any are missing.
+func systemMessageProps(post *model.Post, keys ...string) ([]interface{}, bool) {
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		value, ok := post.Props[key].(string)
+		if !ok {
+			return nil, false
+		}
+		values[i] = value
+	}
+
+	return values, true
+}
+
+// renderSystemMessageFromProps renders messages whose translations are formatted with fmt using the given props.
+func renderSystemMessageFromProps(translationId string, keys ...string) systemMessageRenderer {
+	return func(post *model.Post, T i18n.TranslateFunc) (string, bool) {
+		values, ok := systemMessageProps(post, keys...)
+		if !ok {
+			return "", false
+		}
+
+		return fmt.Sprintf(T(translationId), values...), true
+	}
+}
+
+func renderHeaderChangeMessage(post *model.Post, T i18n.TranslateFunc) (string, bool) {
+	values, ok := systemMessageProps(post, "username", "old_header", "new_header")
+	if !ok {
+		return "", false
+	}
+
+	username, oldHeader, newHeader := values[0], values[1].(string), values[2].(string)
+	if oldHeader == "" {
+		return fmt.Sprintf(T("api.channel.post_update_channel_header_message_and_forget.updated_to"), username, newHeader), true
+	} else if newHeader == "" {
+		return fmt.Sprintf(T("api.channel.post_update_channel_header_message_and_forget.removed"), username, oldHeader), true
+	}
+
+	return fmt.Sprintf(T("api.channel.post_update_channel_header_message_and_forget.updated_from"), username, oldHeader, newHeader), true
+}
+
+func renderPurposeChangeMessage(post *model.Post, T i18n.TranslateFunc) (string, bool) {
+	values, ok := systemMessageProps(post, "username", "old_purpose", "new_purpose")
+	if !ok {
+		return "", false
+	}
+
+	username, oldPurpose, newPurpose := values[0], values[1].(string), values[2].(string)
+	if oldPurpose == "" {
+		return fmt.Sprintf(T("app.channel.post_update_channel_purpose_message.updated_to"), username, newPurpose), true
+	} else if newPurpose == "" {
+		return fmt.Sprintf(T("app.channel.post_update_channel_purpose_message.removed"), username, oldPurpose), true
+	}
+
+	return fmt.Sprintf(T("app.channel.post_update_channel_purpose_message.updated_from"), username, oldPurpose, newPurpose), true
+}
+
+func renderChannelPrivacyMessage(post *model.Post, T i18n.TranslateFunc) (string, bool) {
+	values, ok := systemMessageProps(post, "new_type")
+	if !ok {
+		return "", false
+	}
+
+	switch values[0] {
+	case model.CHANNEL_OPEN:
+		return T("api.channel.change_channel_privacy.private_to_public"), true
+	case model.CHANNEL_PRIVATE:
+		return T("api.channel.change_channel_privacy.public_to_private"), true
+	}
+
+	return "", false
+}
+
+// PostListWithLocalizedSystemMessages returns a copy of list where the text of each system message is in userId's
+// language. The text that system messages are stored with is in the server's default language.
+func (a *App) PostListWithLocalizedSystemMessages(list *model.PostList, userId string) *model.PostList {
+	hasSystemMessages := false
+	for _, post := range list.Posts {
+		if _, ok := systemMessageRenderers[post.Type]; ok {
+			hasSystemMessages = true
+			break
+		}
+	}
+
+	if !hasSystemMessages {
+		return list
+	}
+
+	locale := model.DEFAULT_LOCALE
+	if user, err := a.GetUser(userId); err == nil {
+		locale = user.Locale
+	}
+	T := utils.GetUserTranslations(locale)
+
+	copy := *list
+	copy.Posts = make(map[string]*model.Post, len(list.Posts))
+	for id, post := range list.Posts {
+		message := RenderSystemMessage(post, T)
+		if message == post.Message {
+			copy.Posts[id] = post
+			continue
+		}
+
+		pcopy := *post
+		pcopy.Message = message
+		copy.Posts[id] = &pcopy
+	}
+
+	return &copy
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+func TestRenderSystemMessage(t *testing.T) {
+	en := utils.GetUserTranslations("en")
+	de := utils.GetUserTranslations("de")
+
+	for name, tc := range map[string]struct {
+		Post     *model.Post
+		Expected map[string]string
+	}{
+		"left the channel": {
+			Post: &model.Post{
+				Type:    model.POST_LEAVE_CHANNEL,
+				Message: "stored",
+				Props:   model.StringInterface{"username": "alice"},
+			},
+			Expected: map[string]string{
+				"en": "alice left the channel.",
+				"de": "alice hat den Kanal verlassen.",
+			},
+		},
+		"added to the channel": {
+			Post: &model.Post{
+				Type:    model.POST_ADD_TO_CHANNEL,
+				Message: "stored",
+				Props:   model.StringInterface{"username": "alice", "addedUsername": "bob"},
+			},
+			Expected: map[string]string{
+				"en": "bob added to the channel by alice.",
+				"de": "bob wurde von alice zum Kanal hinzugefügt.",
+			},
+		},
+		"header set": {
+			Post: &model.Post{
+				Type:    model.POST_HEADER_CHANGE,
+				Message: "stored",
+				Props:   model.StringInterface{"username": "alice", "old_header": "", "new_header": "new"},
+			},
+			Expected: map[string]string{
+				"en": "alice updated the channel header to: new",
+				"de": "alice hat die Kanalüberschrift geändert auf: new",
+			},
+		},
+		"made private": {
+			Post: &model.Post{
+				Type:    model.POST_CHANGE_CHANNEL_PRIVACY,
+				Message: "stored",
+				Props:   model.StringInterface{"username": "alice", "new_type": model.CHANNEL_PRIVATE},
+			},
+			Expected: map[string]string{
+				"en": en("api.channel.change_channel_privacy.public_to_private"),
+				"de": de("api.channel.change_channel_privacy.public_to_private"),
+			},
+		},
+		"missing props": {
+			Post: &model.Post{
+				Type:    model.POST_CHANGE_CHANNEL_PRIVACY,
+				Message: "stored",
+				Props:   model.StringInterface{"username": "alice"},
+			},
+			Expected: map[string]string{
+				"en": "stored",
+				"de": "stored",
+			},
+		},
+		"not a system message": {
+			Post: &model.Post{
+				Message: "stored",
+				Props:   model.StringInterface{"username": "alice"},
+			},
+			Expected: map[string]string{
+				"en": "stored",
+				"de": "stored",
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			for locale, expected := range tc.Expected {
+				message := RenderSystemMessage(tc.Post, utils.GetUserTranslations(locale))
+				assert.Equal(t, expected, message, locale)
+			}
+		})
+	}
+}
+
+func TestPostListWithLocalizedSystemMessages(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.BasicUser2.Locale = "de"
+	_, err := th.App.UpdateUser(th.BasicUser2, false)
+	assert.Nil(t, err)
+
+	post := &model.Post{
+		Id:        model.NewId(),
+		ChannelId: th.BasicChannel.Id,
+		UserId:    th.BasicUser.Id,
+		Type:      model.POST_LEAVE_CHANNEL,
+		Message:   "stored",
+		Props:     model.StringInterface{"username": "alice"},
+	}
+	list := model.NewPostList()
+	list.AddPost(post)
+
+	assert.Equal(t, "alice left the channel.", th.App.PostListWithLocalizedSystemMessages(list, th.BasicUser.Id).Posts[post.Id].Message)
+	assert.Equal(t, "alice hat den Kanal verlassen.", th.App.PostListWithLocalizedSystemMessages(list, th.BasicUser2.Id).Posts[post.Id].Message)
+	assert.Equal(t, "stored", post.Message, "shouldn't have changed the original post")
+}
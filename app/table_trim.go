@@ -0,0 +1,166 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+const (
+	TRIM_TABLE_AUDITS = "audits"
+	TRIM_TABLE_JOBS   = "jobs"
+)
+
+// TrimTables lists the tables that can be trimmed by their retention settings.
+var TrimTables = []string{TRIM_TABLE_AUDITS, TRIM_TABLE_JOBS}
+
+// TableTrim is what was trimmed from a table, or what would have been for a dry run. Rows created before Before are
+// trimmed, and Before is 0 if the table's retention doesn't trim anything.
+type TableTrim struct {
+	Table  string `json:"table"`
+	Before int64  `json:"before"`
+	Count  int64  `json:"count"`
+	DryRun bool   `json:"dry_run"`
+}
+
+func (t *TableTrim) String() string {
+	if t.Before == 0 {
+		return fmt.Sprintf("%v: nothing to trim", t.Table)
+	}
+
+	verb := "trimmed"
+	if t.DryRun {
+		verb = "would trim"
+	}
+
+	return fmt.Sprintf("%v: %v %v rows created before %v", t.Table, verb, t.Count, time.Unix(0, t.Before*int64(time.Millisecond)).UTC().Format(time.RFC3339))
+}
+
+// tableTrimmer trims one of the tables that's kept to a maximum age and number of rows.
+type tableTrimmer struct {
+	retentionDays int
+	maxRows       int
+	maxRowsCutoff func(maxRows int64) store.StoreChannel
+	countBefore   func(before int64) store.StoreChannel
+	deleteBatch   func(before int64, limit int64) store.StoreChannel
+}
+
+func (a *App) getTableTrimmer(table string) (*tableTrimmer, *model.AppError) {
+	settings := a.Config().ServiceSettings
+
+	switch table {
+	case TRIM_TABLE_AUDITS:
+		return &tableTrimmer{
+			retentionDays: *settings.AuditRetentionDays,
+			maxRows:       *settings.AuditRetentionMaxRows,
+			maxRowsCutoff: a.Srv.Store.Audit().GetMaxRowsCutoff,
+			countBefore:   a.Srv.Store.Audit().GetCountBefore,
+			deleteBatch:   a.Srv.Store.Audit().PermanentDeleteBatch,
+		}, nil
+	case TRIM_TABLE_JOBS:
+		// Jobs that are waiting to run or running are never trimmed
+		return &tableTrimmer{
+			retentionDays: *settings.JobRetentionDays,
+			maxRows:       *settings.JobRetentionMaxRows,
+			maxRowsCutoff: a.Srv.Store.Job().GetFinishedMaxRowsCutoff,
+			countBefore:   a.Srv.Store.Job().GetFinishedCountBefore,
+			deleteBatch:   a.Srv.Store.Job().PermanentDeleteFinishedBatch,
+		}, nil
+	}
+
+	return nil, model.NewAppError("TrimTable", "app.table_trim.unknown_table.app_error", map[string]interface{}{"Table": table}, "", http.StatusBadRequest)
+}
+
+// trimBefore returns the time before which rows are older than the retention days or aren't among the newest
+// maximum number of rows, or 0 if neither is set or nothing is past them.
+func (t *tableTrimmer) trimBefore(now int64) (int64, *model.AppError) {
+	var before int64
+	if t.retentionDays > 0 {
+		before = now - int64(t.retentionDays)*24*60*60*1000
+	}
+
+	if t.maxRows > 0 {
+		result := <-t.maxRowsCutoff(int64(t.maxRows))
+		if result.Err != nil {
+			return 0, result.Err
+		}
+
+		if cutoff := result.Data.(int64); cutoff > before {
+			before = cutoff
+		}
+	}
+
+	return before, nil
+}
+
+// TrimTable deletes the rows of the table that are past its retention settings, TableTrimBatchSize at a time, or
+// only counts them for a dry run.
+func (a *App) TrimTable(table string, dryRun bool) (*TableTrim, *model.AppError) {
+	trimmer, err := a.getTableTrimmer(table)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := trimmer.trimBefore(model.GetMillis())
+	if err != nil {
+		return nil, err
+	}
+
+	trim := &TableTrim{Table: table, Before: before, DryRun: dryRun}
+	if before == 0 {
+		return trim, nil
+	}
+
+	if dryRun {
+		result := <-trimmer.countBefore(before)
+		if result.Err != nil {
+			return nil, result.Err
+		}
+
+		trim.Count = result.Data.(int64)
+		return trim, nil
+	}
+
+	batchSize := int64(*a.Config().ServiceSettings.TableTrimBatchSize)
+	for {
+		result := <-trimmer.deleteBatch(before, batchSize)
+		if result.Err != nil {
+			return trim, result.Err
+		}
+
+		count := result.Data.(int64)
+		if count == 0 {
+			break
+		}
+		trim.Count += count
+
+		if a.Metrics != nil {
+			a.Metrics.AddTableRowsTrimmed(table, count)
+		}
+
+		time.Sleep(CLEANUP_BATCH_DELAY_MILLISECONDS * time.Millisecond)
+	}
+
+	if trim.Count > 0 {
+		mlog.Info(fmt.Sprintf("Trimmed %v rows from %v", trim.Count, table))
+	}
+
+	return trim, nil
+}
+
+// TrimTablesByRetention trims each of the tables that have retention settings, carrying on to the others if one of
+// them fails.
+func (a *App) TrimTablesByRetention() {
+	for _, table := range TrimTables {
+		if _, err := a.TrimTable(table, false); err != nil {
+			mlog.Error(fmt.Sprintf("Unable to trim %v, err=%v", table, err))
+		}
+	}
+}
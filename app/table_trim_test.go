@@ -0,0 +1,98 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func TestTrimTable(t *testing.T) {
+	th := Setup()
+	defer th.TearDown()
+
+	t.Run("jobs", func(t *testing.T) {
+		now := model.GetMillis()
+		day := int64(24 * 60 * 60 * 1000)
+
+		saveJob := func(status string, createAt int64) *model.Job {
+			return store.Must(th.App.Srv.Store.Job().Save(&model.Job{
+				Id:       model.NewId(),
+				Type:     model.JOB_TYPE_MESSAGE_EXPORT,
+				Status:   status,
+				CreateAt: createAt,
+			})).(*model.Job)
+		}
+
+		oldFinished := saveJob(model.JOB_STATUS_SUCCESS, now-60*day)
+		oldPending := saveJob(model.JOB_STATUS_PENDING, now-60*day)
+		oldInProgress := saveJob(model.JOB_STATUS_IN_PROGRESS, now-60*day)
+		finished1 := saveJob(model.JOB_STATUS_SUCCESS, now+time.Hour.Nanoseconds()/1e6)
+		finished2 := saveJob(model.JOB_STATUS_ERROR, now+2*time.Hour.Nanoseconds()/1e6)
+		finished3 := saveJob(model.JOB_STATUS_CANCELED, now+3*time.Hour.Nanoseconds()/1e6)
+		newPending := saveJob(model.JOB_STATUS_PENDING, now+4*time.Hour.Nanoseconds()/1e6)
+
+		trim, err := th.App.TrimTable(TRIM_TABLE_JOBS, true)
+		require.Nil(t, err)
+		assert.Zero(t, trim.Before, "nothing should be trimmed without retention settings")
+
+		th.App.UpdateConfig(func(cfg *model.Config) {
+			*cfg.ServiceSettings.JobRetentionDays = 30
+			*cfg.ServiceSettings.JobRetentionMaxRows = 2
+			*cfg.ServiceSettings.TableTrimBatchSize = 1
+		})
+
+		trim, err = th.App.TrimTable(TRIM_TABLE_JOBS, true)
+		require.Nil(t, err)
+		assert.Equal(t, finished2.CreateAt, trim.Before, "the newest finished jobs should be kept")
+		assert.True(t, trim.Count >= 2)
+
+		result := <-th.App.Srv.Store.Job().Get(oldFinished.Id)
+		require.Nil(t, result.Err, "nothing should be deleted for a dry run")
+
+		trim, err = th.App.TrimTable(TRIM_TABLE_JOBS, false)
+		require.Nil(t, err)
+		assert.True(t, trim.Count >= 2)
+
+		for _, job := range []*model.Job{oldFinished, finished1} {
+			result := <-th.App.Srv.Store.Job().Get(job.Id)
+			assert.NotNil(t, result.Err, "job %v should have been trimmed", job.Status)
+		}
+
+		for _, job := range []*model.Job{oldPending, oldInProgress, finished2, finished3, newPending} {
+			result := <-th.App.Srv.Store.Job().Get(job.Id)
+			assert.Nil(t, result.Err, "job %v should have been kept", job.Status)
+		}
+	})
+
+	t.Run("audits", func(t *testing.T) {
+		userId := model.NewId()
+		for i := 0; i < 3; i++ {
+			// Make sure that each audit is created at a different time than the last
+			time.Sleep(time.Millisecond)
+			store.Must(th.App.Srv.Store.Audit().Save(&model.Audit{UserId: userId, Action: "action"}))
+		}
+
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.AuditRetentionMaxRows = 2 })
+
+		trim, err := th.App.TrimTable(TRIM_TABLE_AUDITS, false)
+		require.Nil(t, err)
+		assert.True(t, trim.Count >= 1)
+
+		audits := store.Must(th.App.Srv.Store.Audit().Get(userId, 0, 10)).(model.Audits)
+		assert.Len(t, audits, 2, "the newest audits should be kept")
+	})
+
+	t.Run("unknown table", func(t *testing.T) {
+		_, err := th.App.TrimTable("posts", true)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.table_trim.unknown_table.app_error", err.Id)
+	})
+}
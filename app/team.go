@@ -20,6 +20,8 @@ import (
 	"github.com/mattermost/mattermost-server/utils"
 )
 
+const TEAM_MEMBER_COUNTS_RECONCILE_BATCH_SIZE = 1000
+
 func (a *App) CreateTeam(team *model.Team) (*model.Team, *model.AppError) {
 	if result := <-a.Srv.Store.Team().Save(team); result.Err != nil {
 		return nil, result.Err
@@ -104,6 +106,8 @@ func (a *App) UpdateTeam(team *model.Team) (*model.Team, *model.AppError) {
 	oldTeam.CompanyName = team.CompanyName
 	oldTeam.AllowedDomains = team.AllowedDomains
 	oldTeam.LastTeamIconUpdate = team.LastTeamIconUpdate
+	oldTeam.WelcomeMessage = team.WelcomeMessage
+	oldTeam.InviteIdDisabled = team.InviteIdDisabled
 
 	if result := <-a.Srv.Store.Team().Update(oldTeam); result.Err != nil {
 		return nil, result.Err
@@ -223,7 +227,7 @@ func (a *App) AddUserToTeamByToken(userId string, tokenId string) (*model.Team,
 	}
 
 	token := result.Data.(*model.Token)
-	if token.Type != TOKEN_TYPE_TEAM_INVITATION {
+	if token.Type != TOKEN_TYPE_TEAM_INVITATION && token.Type != TOKEN_TYPE_GUEST_INVITATION {
 		return nil, model.NewAppError("AddUserToTeamByToken", "api.user.create_user.signup_link_invalid.app_error", nil, "", http.StatusBadRequest)
 	}
 
@@ -251,10 +255,18 @@ func (a *App) AddUserToTeamByToken(userId string, tokenId string) (*model.Team,
 		user = result.Data.(*model.User)
 	}
 
+	// Guests can only use guest invitations, and guest invitations can't be used to add members to
+	// a team, since they'd skip the channel restrictions the invitation was sent with.
+	if user.IsGuest() != (token.Type == TOKEN_TYPE_GUEST_INVITATION) {
+		return nil, model.NewAppError("AddUserToTeamByToken", "api.user.create_user.invalid_invitation_type.app_error", nil, "", http.StatusBadRequest)
+	}
+
 	if err := a.JoinUserToTeam(team, user, ""); err != nil {
 		return nil, err
 	}
 
+	a.joinUserToInvitedChannels(team, user, tokenData["channels"])
+
 	if err := a.DeleteToken(token); err != nil {
 		return nil, err
 	}
@@ -263,14 +275,11 @@ func (a *App) AddUserToTeamByToken(userId string, tokenId string) (*model.Team,
 }
 
 func (a *App) AddUserToTeamByInviteId(inviteId string, userId string) (*model.Team, *model.AppError) {
-	tchan := a.Srv.Store.Team().GetByInviteId(inviteId)
 	uchan := a.Srv.Store.User().Get(userId)
 
-	var team *model.Team
-	if result := <-tchan; result.Err != nil {
-		return nil, result.Err
-	} else {
-		team = result.Data.(*model.Team)
+	team, err := a.GetTeamByInviteId(inviteId)
+	if err != nil {
+		return nil, err
 	}
 
 	var user *model.User
@@ -298,7 +307,9 @@ func (a *App) joinUserToTeam(team *model.Team, user *model.User) (*model.TeamMem
 		Roles:  model.TEAM_USER_ROLE_ID,
 	}
 
-	if team.Email == user.Email {
+	if user.IsGuest() {
+		tm.Roles = model.TEAM_GUEST_ROLE_ID
+	} else if team.Email == user.Email {
 		tm.Roles = model.TEAM_USER_ROLE_ID + " " + model.TEAM_ADMIN_ROLE_ID
 	}
 
@@ -333,6 +344,16 @@ func (a *App) joinUserToTeam(team *model.Team, user *model.User) (*model.TeamMem
 }
 
 func (a *App) JoinUserToTeam(team *model.Team, user *model.User, userRequestorId string) *model.AppError {
+	// users are welcomed when they join their first team, which for most of them is as their account is created
+	firstTeam := false
+	if *a.Config().AnnouncementSettings.EnableWelcomeMessage && !user.IsBot {
+		if result := <-a.Srv.Store.Team().GetTeamsForUser(user.Id); result.Err != nil {
+			mlog.Error(fmt.Sprintf("Failed to get the teams of a user joining a team, user_id=%s, err=%v", user.Id, result.Err), mlog.String("user_id", user.Id))
+		} else {
+			firstTeam = len(result.Data.([]*model.TeamMember)) == 0
+		}
+	}
+
 	if _, alreadyAdded, err := a.joinUserToTeam(team, user); err != nil {
 		return err
 	} else if alreadyAdded {
@@ -349,9 +370,18 @@ func (a *App) JoinUserToTeam(team *model.Team, user *model.User, userRequestorId
 		channelRole = model.CHANNEL_USER_ROLE_ID + " " + model.CHANNEL_ADMIN_ROLE_ID
 	}
 
-	// Soft error if there is an issue joining the default channels
-	if err := a.JoinDefaultChannels(team.Id, user, channelRole, userRequestorId); err != nil {
-		mlog.Error(fmt.Sprintf("Encountered an issue joining default channels user_id=%s, team_id=%s, err=%v", user.Id, team.Id, err), mlog.String("user_id", user.Id))
+	// Guests only join the channels they're explicitly added to
+	if !user.IsGuest() {
+		// Soft error if there is an issue joining the default channels
+		if err := a.JoinDefaultChannels(team.Id, user, channelRole, userRequestorId); err != nil {
+			mlog.Error(fmt.Sprintf("Encountered an issue joining default channels user_id=%s, team_id=%s, err=%v", user.Id, team.Id, err), mlog.String("user_id", user.Id))
+		}
+	}
+
+	if firstTeam {
+		if err := a.sendWelcomeMessage(team, user); err != nil {
+			mlog.Error(fmt.Sprintf("Failed to send the welcome message user_id=%s, team_id=%s, err=%v", user.Id, team.Id, err), mlog.String("user_id", user.Id))
+		}
 	}
 
 	a.ClearSessionCacheForUser(user.Id)
@@ -365,6 +395,51 @@ func (a *App) JoinUserToTeam(team *model.Team, user *model.User, userRequestorId
 	return nil
 }
 
+// sendWelcomeMessage sends the team's welcome message, or AnnouncementSettings.WelcomeMessage if the team doesn't
+// have one, to the user as a direct message from the system bot.
+func (a *App) sendWelcomeMessage(team *model.Team, user *model.User) *model.AppError {
+	message := *a.Config().AnnouncementSettings.WelcomeMessage
+	if len(team.WelcomeMessage) > 0 {
+		message = team.WelcomeMessage
+	}
+
+	firstName := user.FirstName
+	if len(firstName) == 0 {
+		firstName = user.Username
+	}
+
+	text, err := model.RenderWelcomeMessage(message, model.WelcomeMessageValues{UserFirstName: firstName, TeamName: team.DisplayName})
+	if err != nil {
+		return model.NewAppError("sendWelcomeMessage", "app.team.send_welcome_message.render.app_error", nil, "team_id="+team.Id+", "+err.Error(), http.StatusInternalServerError)
+	}
+
+	if len(strings.TrimSpace(text)) == 0 {
+		return nil
+	}
+
+	bot, appErr := a.EnsureSystemBot()
+	if appErr != nil {
+		return appErr
+	}
+
+	channel, appErr := a.GetDirectChannel(bot.Id, user.Id)
+	if appErr != nil {
+		return appErr
+	}
+
+	post := &model.Post{
+		ChannelId: channel.Id,
+		UserId:    bot.Id,
+		Message:   text,
+	}
+
+	if _, appErr := a.CreatePost(post, channel, false); appErr != nil {
+		return appErr
+	}
+
+	return nil
+}
+
 func (a *App) GetTeam(teamId string) (*model.Team, *model.AppError) {
 	if result := <-a.Srv.Store.Team().Get(teamId); result.Err != nil {
 		return nil, result.Err
@@ -382,12 +457,20 @@ func (a *App) GetTeamByName(name string) (*model.Team, *model.AppError) {
 	}
 }
 
+// GetTeamByInviteId returns the team that the invite id belongs to, unless the team has stopped its
+// invite id from being used.
 func (a *App) GetTeamByInviteId(inviteId string) (*model.Team, *model.AppError) {
-	if result := <-a.Srv.Store.Team().GetByInviteId(inviteId); result.Err != nil {
+	result := <-a.Srv.Store.Team().GetByInviteId(inviteId)
+	if result.Err != nil {
 		return nil, result.Err
-	} else {
-		return result.Data.(*model.Team), nil
 	}
+
+	team := result.Data.(*model.Team)
+	if team.InviteIdDisabled {
+		return nil, model.NewAppError("GetTeamByInviteId", "app.team.invite_id_disabled.app_error", nil, "team_id="+team.Id, http.StatusForbidden)
+	}
+
+	return team, nil
 }
 
 func (a *App) GetAllTeams() ([]*model.Team, *model.AppError) {
@@ -620,12 +703,23 @@ func (a *App) LeaveTeam(team *model.Team, user *model.User, requestorId string)
 		channelList = result.Data.(*model.ChannelList)
 	}
 
+	historyActorId := requestorId
+	if historyActorId == "" {
+		historyActorId = model.CHANNEL_MEMBER_HISTORY_ACTOR_SYSTEM
+	}
+
 	for _, channel := range *channelList {
 		if !channel.IsGroupOrDirect() {
 			a.InvalidateCacheForChannelMembers(channel.Id)
 			if result := <-a.Srv.Store.Channel().RemoveMember(channel.Id, user.Id); result.Err != nil {
 				return result.Err
 			}
+			if result := <-a.Srv.Store.ChannelMemberHistory().LogLeaveEvent(user.Id, channel.Id, model.GetMillis(), historyActorId, model.CHANNEL_MEMBER_LEAVE_REASON_LEFT_TEAM); result.Err != nil {
+				mlog.Warn(fmt.Sprintf("Failed to update ChannelMemberHistory table %v", result.Err))
+			}
+			if result := <-a.Srv.Store.Thread().DeleteMembershipsForUserInChannel(user.Id, channel.Id); result.Err != nil {
+				return result.Err
+			}
 		}
 	}
 
@@ -670,6 +764,10 @@ func (a *App) LeaveTeam(team *model.Team, user *model.User, requestorId string)
 		return result.Err
 	}
 
+	if result := <-a.Srv.Store.Channel().DeleteSidebarCategoriesForTeamMember(user.Id, team.Id); result.Err != nil {
+		return result.Err
+	}
+
 	a.ClearSessionCacheForUser(user.Id)
 	a.InvalidateCacheForUser(user.Id)
 
@@ -679,15 +777,15 @@ func (a *App) LeaveTeam(team *model.Team, user *model.User, requestorId string)
 func (a *App) postLeaveTeamMessage(user *model.User, channel *model.Channel) *model.AppError {
 	post := &model.Post{
 		ChannelId: channel.Id,
-		Message:   fmt.Sprintf(utils.T("api.team.leave.left"), user.Username),
 		Type:      model.POST_LEAVE_TEAM,
 		UserId:    user.Id,
 		Props: model.StringInterface{
 			"username": user.Username,
 		},
 	}
+	post.Message = RenderSystemMessage(post, utils.T)
 
-	if _, err := a.CreatePost(post, channel, false); err != nil {
+	if _, err := a.createJoinLeavePost(post, channel); err != nil {
 		return model.NewAppError("postRemoveFromChannelMessage", "api.channel.post_user_add_remove_message_and_forget.error", nil, err.Error(), http.StatusInternalServerError)
 	}
 
@@ -697,15 +795,15 @@ func (a *App) postLeaveTeamMessage(user *model.User, channel *model.Channel) *mo
 func (a *App) postRemoveFromTeamMessage(user *model.User, channel *model.Channel) *model.AppError {
 	post := &model.Post{
 		ChannelId: channel.Id,
-		Message:   fmt.Sprintf(utils.T("api.team.remove_user_from_team.removed"), user.Username),
 		Type:      model.POST_REMOVE_FROM_TEAM,
 		UserId:    user.Id,
 		Props: model.StringInterface{
 			"username": user.Username,
 		},
 	}
+	post.Message = RenderSystemMessage(post, utils.T)
 
-	if _, err := a.CreatePost(post, channel, false); err != nil {
+	if _, err := a.createJoinLeavePost(post, channel); err != nil {
 		return model.NewAppError("postRemoveFromTeamMessage", "api.channel.post_user_add_remove_message_and_forget.error", nil, err.Error(), http.StatusInternalServerError)
 	}
 
@@ -755,6 +853,179 @@ func (a *App) InviteNewUsersToTeam(emailList []string, teamId, senderId string)
 	return nil
 }
 
+// InviteGuestsToChannels sends guest invitations for the given channels of a team. Every channel must
+// be an open or private channel on the team.
+func (a *App) InviteGuestsToChannels(teamId string, guestsInvite *model.GuestsInvite, senderId string) *model.AppError {
+	if !*a.Config().GuestAccountsSettings.Enable {
+		return model.NewAppError("InviteGuestsToChannels", "api.team.invite_guests.disabled.app_error", nil, "", http.StatusNotImplemented)
+	}
+
+	if err := guestsInvite.IsValid(); err != nil {
+		return err
+	}
+
+	tchan := a.Srv.Store.Team().Get(teamId)
+	uchan := a.Srv.Store.User().Get(senderId)
+
+	var team *model.Team
+	if result := <-tchan; result.Err != nil {
+		return result.Err
+	} else {
+		team = result.Data.(*model.Team)
+	}
+
+	var user *model.User
+	if result := <-uchan; result.Err != nil {
+		return result.Err
+	} else {
+		user = result.Data.(*model.User)
+	}
+
+	var channels []*model.Channel
+	for _, channelId := range guestsInvite.Channels {
+		channel, err := a.GetChannel(channelId)
+		if err != nil {
+			return err
+		}
+
+		if channel.TeamId != team.Id || channel.DeleteAt != 0 || (channel.Type != model.CHANNEL_OPEN && channel.Type != model.CHANNEL_PRIVATE) {
+			return model.NewAppError("InviteGuestsToChannels", "api.team.invite_guests.channel.app_error", nil, "channel_id="+channel.Id, http.StatusBadRequest)
+		}
+
+		channels = append(channels, channel)
+	}
+
+	nameFormat := *a.Config().TeamSettings.TeammateNameDisplay
+	a.SendGuestInviteEmails(team, channels, user.GetDisplayName(nameFormat), guestsInvite.Emails, a.GetSiteURL(), guestsInvite.Message)
+
+	return nil
+}
+
+// SendTeamInvites queues an invitation email for each invite, with the invitee's role and the channels
+// that they'll be added to. Invites that can't be sent, like ones for people who are already on the team,
+// are reported in the results rather than failing the others. The invitation of each one that was queued
+// is saved so that its status can be followed.
+func (a *App) SendTeamInvites(teamId string, invites []*model.Invite, senderId string) ([]*model.InviteResult, *model.AppError) {
+	if len(invites) == 0 {
+		return nil, model.NewAppError("SendTeamInvites", "api.team.invite_members.no_one.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	tchan := a.Srv.Store.Team().Get(teamId)
+	uchan := a.Srv.Store.User().Get(senderId)
+
+	var team *model.Team
+	if result := <-tchan; result.Err != nil {
+		return nil, result.Err
+	} else {
+		team = result.Data.(*model.Team)
+	}
+
+	if result := <-uchan; result.Err != nil {
+		return nil, result.Err
+	}
+
+	channels := map[string]*model.Channel{}
+	checkInviteChannels := func(invite *model.Invite) *model.AppError {
+		for _, channelId := range invite.Channels {
+			channel, ok := channels[channelId]
+			if !ok {
+				var err *model.AppError
+				if channel, err = a.GetChannel(channelId); err != nil {
+					return err
+				}
+				channels[channelId] = channel
+			}
+
+			if channel.TeamId != team.Id || channel.DeleteAt != 0 || (channel.Type != model.CHANNEL_OPEN && channel.Type != model.CHANNEL_PRIVATE) {
+				return model.NewAppError("SendTeamInvites", "app.team.send_invites.channel.app_error", nil, "channel_id="+channelId, http.StatusBadRequest)
+			}
+		}
+
+		return nil
+	}
+
+	seen := map[string]bool{}
+	sendInvite := func(invite *model.Invite) (string, *model.AppError) {
+		invite.PreSave()
+		if err := invite.IsValid(); err != nil {
+			return "", err
+		}
+
+		if seen[invite.Email] {
+			return "", model.NewAppError("SendTeamInvites", "app.team.send_invites.duplicate.app_error", nil, "email="+invite.Email, http.StatusBadRequest)
+		}
+		seen[invite.Email] = true
+
+		if err := a.checkTeamInviteAllowed(team, invite); err != nil {
+			return "", err
+		}
+
+		if err := checkInviteChannels(invite); err != nil {
+			return "", err
+		}
+
+		result := <-a.Srv.Store.TeamInvitation().Save(model.NewTeamInvitation(team.Id, senderId, invite))
+		if result.Err != nil {
+			return "", result.Err
+		}
+		invitation := result.Data.(*model.TeamInvitation)
+
+		a.queueTeamInvitation(invitation, MAIL_PRIORITY_LOW)
+
+		return invitation.Id, nil
+	}
+
+	results := make([]*model.InviteResult, 0, len(invites))
+	for _, invite := range invites {
+		invitationId, err := sendInvite(invite)
+		if err != nil {
+			mlog.Warn(fmt.Sprintf("Unable to send invitation email=%v err=%v", invite.Email, err.Error()))
+		}
+
+		results = append(results, &model.InviteResult{Email: invite.Email, InvitationId: invitationId, Error: err})
+	}
+
+	return results, nil
+}
+
+// checkTeamInviteAllowed checks that the invite's role can be invited to the team and that whoever it's for isn't
+// already a member of it.
+func (a *App) checkTeamInviteAllowed(team *model.Team, invite *model.Invite) *model.AppError {
+	if invite.Role == model.INVITE_ROLE_GUEST {
+		if !*a.Config().GuestAccountsSettings.Enable {
+			return model.NewAppError("SendTeamInvites", "api.team.invite_guests.disabled.app_error", nil, "", http.StatusNotImplemented)
+		}
+	} else if !a.isTeamEmailAddressAllowed(invite.Email) {
+		return model.NewAppError("SendTeamInvites", "api.team.invite_members.invalid_email.app_error", map[string]interface{}{"Addresses": invite.Email}, "", http.StatusBadRequest)
+	}
+
+	if result := <-a.Srv.Store.User().GetByEmail(invite.Email); result.Err == nil {
+		user := result.Data.(*model.User)
+		if result := <-a.Srv.Store.Team().GetMember(team.Id, user.Id); result.Err == nil && result.Data.(*model.TeamMember).DeleteAt == 0 {
+			return model.NewAppError("SendTeamInvites", "api.team.invite_members.already.app_error", nil, "email="+invite.Email, http.StatusBadRequest)
+		}
+	}
+
+	return nil
+}
+
+// joinUserToInvitedChannels adds a user to the space separated channel ids of an invitation. Like
+// the default channels of a team, a channel that can't be joined is logged and skipped.
+func (a *App) joinUserToInvitedChannels(team *model.Team, user *model.User, channelIds string) {
+	for _, channelId := range strings.Fields(channelIds) {
+		channel, err := a.GetChannel(channelId)
+		if err == nil && channel.TeamId != team.Id {
+			err = model.NewAppError("joinUserToInvitedChannels", "api.team.invite_guests.channel.app_error", nil, "channel_id="+channelId, http.StatusBadRequest)
+		}
+		if err == nil {
+			_, err = a.AddChannelMember(user.Id, channel, "", "")
+		}
+		if err != nil {
+			mlog.Error(fmt.Sprintf("Encountered an issue adding a user to an invited channel user_id=%s, channel_id=%s, err=%v", user.Id, channelId, err), mlog.String("user_id", user.Id))
+		}
+	}
+}
+
 func (a *App) FindTeamByName(name string) bool {
 	if result := <-a.Srv.Store.Team().GetByName(name); result.Err != nil {
 		return false
@@ -764,6 +1035,8 @@ func (a *App) FindTeamByName(name string) bool {
 }
 
 func (a *App) GetTeamsUnreadForUser(excludeTeamId string, userId string) ([]*model.TeamUnread, *model.AppError) {
+	a.flushChannelViewsForUser(userId)
+
 	if result := <-a.Srv.Store.Team().GetChannelUnreadsForAllTeams(excludeTeamId, userId); result.Err != nil {
 		return nil, result.Err
 	} else {
@@ -811,38 +1084,15 @@ func (a *App) PermanentDeleteTeamId(teamId string) *model.AppError {
 	return a.PermanentDeleteTeam(team)
 }
 
+// PermanentDeleteTeam archives the team and then permanently deletes it along with everything on it, waiting until
+// it's done. Teams that are too large to wait for are deleted by a job made with MarkTeamForPermanentDeletion.
 func (a *App) PermanentDeleteTeam(team *model.Team) *model.AppError {
 	team.DeleteAt = model.GetMillis()
 	if result := <-a.Srv.Store.Team().Update(team); result.Err != nil {
 		return result.Err
 	}
 
-	if result := <-a.Srv.Store.Channel().GetTeamChannels(team.Id); result.Err != nil {
-		if result.Err.Id != "store.sql_channel.get_channels.not_found.app_error" {
-			return result.Err
-		}
-	} else {
-		channels := result.Data.(*model.ChannelList)
-		for _, c := range *channels {
-			a.PermanentDeleteChannel(c)
-		}
-	}
-
-	if result := <-a.Srv.Store.Team().RemoveAllMembersByTeam(team.Id); result.Err != nil {
-		return result.Err
-	}
-
-	if result := <-a.Srv.Store.Command().PermanentDeleteByTeam(team.Id); result.Err != nil {
-		return result.Err
-	}
-
-	if result := <-a.Srv.Store.Team().PermanentDelete(team.Id); result.Err != nil {
-		return result.Err
-	}
-
-	a.sendTeamEvent(team, model.WEBSOCKET_EVENT_DELETE_TEAM)
-
-	return nil
+	return a.PermanentDeleteTeamWithProgress(&model.TeamDeletionProgress{TeamId: team.Id}, nil)
 }
 
 func (a *App) SoftDeleteTeam(teamId string) *model.AppError {
@@ -883,6 +1133,66 @@ func (a *App) GetTeamStats(teamId string) (*model.TeamStats, *model.AppError) {
 	return stats, nil
 }
 
+// ReconcileTeamMemberCounts recounts the active members of every team, fixing any counts that have drifted from the
+// teams' actual members, and returns how many teams were fixed.
+func (a *App) ReconcileTeamMemberCounts() (int64, *model.AppError) {
+	var corrected int64
+	afterTeamId := ""
+	for {
+		result := <-a.Srv.Store.Team().ReconcileMemberCounts(afterTeamId, TEAM_MEMBER_COUNTS_RECONCILE_BATCH_SIZE)
+		if result.Err != nil {
+			return corrected, result.Err
+		}
+
+		batch := result.Data.(*model.TeamMemberCountsBatch)
+		corrected += batch.Corrected
+		if batch.LastTeamId == "" {
+			return corrected, nil
+		}
+
+		afterTeamId = batch.LastTeamId
+	}
+}
+
+// GetTeamDefaultChannels returns the ids of the channels that new members of a team are added to
+// in addition to Town Square and Off-Topic.
+func (a *App) GetTeamDefaultChannels(teamId string) ([]string, *model.AppError) {
+	if result := <-a.Srv.Store.Team().GetDefaultChannels(teamId); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return result.Data.([]string), nil
+	}
+}
+
+// UpdateTeamDefaultChannels replaces the extra channels that new members of a team are added to. Each
+// channel must be a public channel on the team. Existing members of channels removed from the list
+// are left alone.
+func (a *App) UpdateTeamDefaultChannels(teamId string, channelIds []string) ([]string, *model.AppError) {
+	defaultChannelIds := []string{}
+	for _, channelId := range channelIds {
+		if utils.StringInSlice(channelId, defaultChannelIds) {
+			continue
+		}
+
+		channel, err := a.GetChannel(channelId)
+		if err != nil {
+			return nil, model.NewAppError("UpdateTeamDefaultChannels", "app.team.update_default_channels.channel.app_error", nil, "team_id="+teamId+", channel_id="+channelId+", "+err.Error(), http.StatusBadRequest)
+		}
+
+		if channel.TeamId != teamId || channel.Type != model.CHANNEL_OPEN || channel.DeleteAt != 0 {
+			return nil, model.NewAppError("UpdateTeamDefaultChannels", "app.team.update_default_channels.channel.app_error", nil, "team_id="+teamId+", channel_id="+channelId, http.StatusBadRequest)
+		}
+
+		defaultChannelIds = append(defaultChannelIds, channelId)
+	}
+
+	if result := <-a.Srv.Store.Team().UpdateDefaultChannels(teamId, defaultChannelIds); result.Err != nil {
+		return nil, result.Err
+	}
+
+	return defaultChannelIds, nil
+}
+
 func (a *App) GetTeamIdFromQuery(query url.Values) (string, *model.AppError) {
 	tokenId := query.Get("t")
 	inviteId := query.Get("id")
@@ -907,11 +1217,11 @@ func (a *App) GetTeamIdFromQuery(query url.Values) (string, *model.AppError) {
 
 		return tokenData["teamId"], nil
 	} else if len(inviteId) > 0 {
-		if result := <-a.Srv.Store.Team().GetByInviteId(inviteId); result.Err != nil {
+		if team, err := a.GetTeamByInviteId(inviteId); err != nil {
 			// soft fail, so we still create user but don't auto-join team
-			mlog.Error(fmt.Sprintf("%v", result.Err))
+			mlog.Error(fmt.Sprintf("%v", err))
 		} else {
-			return result.Data.(*model.Team).Id, nil
+			return team.Id, nil
 		}
 	}
 
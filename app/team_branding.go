@@ -0,0 +1,162 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func teamBrandingPath(teamId string) string {
+	return "teams/" + teamId + "/branding/branding.json"
+}
+
+func teamBrandingImagePath(teamId string) string {
+	return "teams/" + teamId + "/branding/image.png"
+}
+
+// GetTeamBranding returns the team's branding, which is blank if the team hasn't set any.
+func (a *App) GetTeamBranding(teamId string) (*model.TeamBranding, *model.AppError) {
+	if len(*a.Config().FileSettings.DriverName) == 0 {
+		return nil, model.NewAppError("GetTeamBranding", "app.team.branding.storage.app_error", nil, "", http.StatusNotImplemented)
+	}
+
+	if _, err := a.GetTeam(teamId); err != nil {
+		return nil, err
+	}
+
+	data, err := a.ReadFile(teamBrandingPath(teamId))
+	if err != nil {
+		// the team hasn't been branded yet
+		return &model.TeamBranding{TeamId: teamId}, nil
+	}
+
+	branding := model.TeamBrandingFromJson(bytes.NewReader(data))
+	if branding == nil {
+		return nil, model.NewAppError("GetTeamBranding", "app.team.branding.read.app_error", nil, "team_id="+teamId, http.StatusInternalServerError)
+	}
+	branding.TeamId = teamId
+
+	return branding, nil
+}
+
+func (a *App) saveTeamBranding(branding *model.TeamBranding) *model.AppError {
+	branding.UpdateAt = model.GetMillis()
+
+	if _, err := a.WriteFile(strings.NewReader(branding.ToJson()), teamBrandingPath(branding.TeamId)); err != nil {
+		return model.NewAppError("saveTeamBranding", "app.team.branding.write.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+// PatchTeamBranding updates the team's branding text or whether it's locked.
+func (a *App) PatchTeamBranding(teamId string, patch *model.TeamBrandingPatch) (*model.TeamBranding, *model.AppError) {
+	branding, err := a.GetTeamBranding(teamId)
+	if err != nil {
+		return nil, err
+	}
+
+	branding.Patch(patch)
+
+	if err := branding.IsValid(); err != nil {
+		return nil, err
+	}
+
+	if err := a.saveTeamBranding(branding); err != nil {
+		return nil, err
+	}
+
+	return branding, nil
+}
+
+// SetTeamBrandingImage stores a PNG, JPEG or GIF image as the team's branding image. It's stored as a PNG like the
+// site's brand image.
+func (a *App) SetTeamBrandingImage(teamId string, imageData *multipart.FileHeader) *model.AppError {
+	if imageData.Size > model.TEAM_BRANDING_IMAGE_MAX_BYTES {
+		return model.NewAppError("SetTeamBrandingImage", "app.team.branding.image.too_large.app_error", map[string]interface{}{"Max": model.TEAM_BRANDING_IMAGE_MAX_BYTES}, "", http.StatusRequestEntityTooLarge)
+	}
+
+	branding, err := a.GetTeamBranding(teamId)
+	if err != nil {
+		return err
+	}
+
+	file, openErr := imageData.Open()
+	if openErr != nil {
+		return model.NewAppError("SetTeamBrandingImage", "app.team.branding.image.open.app_error", nil, openErr.Error(), http.StatusBadRequest)
+	}
+	defer file.Close()
+
+	// Decode image config first to check the type and dimensions before loading the whole thing into memory
+	config, format, decodeErr := image.DecodeConfig(file)
+	if decodeErr != nil {
+		return model.NewAppError("SetTeamBrandingImage", "app.team.branding.image.type.app_error", nil, decodeErr.Error(), http.StatusBadRequest)
+	} else if format != "png" && format != "jpeg" && format != "gif" {
+		return model.NewAppError("SetTeamBrandingImage", "app.team.branding.image.type.app_error", nil, "format="+format, http.StatusBadRequest)
+	} else if config.Width*config.Height > model.MaxImageSize {
+		return model.NewAppError("SetTeamBrandingImage", "app.team.branding.image.too_large.app_error", map[string]interface{}{"Max": model.TEAM_BRANDING_IMAGE_MAX_BYTES}, "", http.StatusRequestEntityTooLarge)
+	}
+
+	file.Seek(0, 0)
+
+	img, _, decodeErr := image.Decode(file)
+	if decodeErr != nil {
+		return model.NewAppError("SetTeamBrandingImage", "app.team.branding.image.type.app_error", nil, decodeErr.Error(), http.StatusBadRequest)
+	}
+
+	buf := new(bytes.Buffer)
+	if encodeErr := png.Encode(buf, img); encodeErr != nil {
+		return model.NewAppError("SetTeamBrandingImage", "app.team.branding.image.encode.app_error", nil, encodeErr.Error(), http.StatusInternalServerError)
+	}
+
+	if _, err := a.WriteFile(buf, teamBrandingImagePath(teamId)); err != nil {
+		return model.NewAppError("SetTeamBrandingImage", "app.team.branding.write.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	branding.LastImageUpdate = model.GetMillis()
+
+	return a.saveTeamBranding(branding)
+}
+
+// RemoveTeamBrandingImage stops the team from using its own branding image.
+func (a *App) RemoveTeamBrandingImage(teamId string) *model.AppError {
+	branding, err := a.GetTeamBranding(teamId)
+	if err != nil {
+		return err
+	}
+
+	if branding.LastImageUpdate == 0 {
+		return nil
+	}
+
+	if err := a.RemoveFile(teamBrandingImagePath(teamId)); err != nil {
+		return model.NewAppError("RemoveTeamBrandingImage", "app.team.branding.write.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	branding.LastImageUpdate = 0
+
+	return a.saveTeamBranding(branding)
+}
+
+// GetTeamBrandingImage returns the team's branding image, falling back to the site's brand image if the team
+// doesn't have one.
+func (a *App) GetTeamBrandingImage(branding *model.TeamBranding) ([]byte, *model.AppError) {
+	if branding.LastImageUpdate == 0 {
+		return a.GetBrandImage()
+	}
+
+	data, err := a.ReadFile(teamBrandingImagePath(branding.TeamId))
+	if err != nil {
+		return nil, model.NewAppError("GetTeamBrandingImage", "app.team.branding.image.read.app_error", nil, err.Error(), http.StatusNotFound)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,263 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+const (
+	TEAM_DELETION_CHANNEL_BATCH_SIZE    = 100
+	TEAM_DELETION_PREFERENCE_BATCH_SIZE = 1000
+
+	// A job that's deleting a team saves its progress after each channel, so one that hasn't for this long was
+	// interrupted, such as by the server stopping, and is resumed by a new job
+	TEAM_DELETION_STALE_JOB_TIMEOUT = time.Hour
+)
+
+// MarkTeamForPermanentDeletion archives the team, if it isn't already, and creates a job to permanently delete it,
+// returning the job. If a job to delete the team is already pending or running, that job is returned instead. A
+// deletion that was interrupted, whether it failed, was canceled or stopped with the server, is resumed by the new job
+// from the stage that it stopped at.
+func (a *App) MarkTeamForPermanentDeletion(team *model.Team) (*model.Job, *model.AppError) {
+	if team.DeleteAt == 0 {
+		if err := a.SoftDeleteTeam(team.Id); err != nil {
+			return nil, err
+		}
+	}
+
+	result := <-a.Srv.Store.Job().GetAllByType(model.JOB_TYPE_TEAM_DELETION)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	var interrupted *model.Job
+	for _, job := range result.Data.([]*model.Job) {
+		if job.Data["team_id"] != team.Id {
+			continue
+		}
+
+		switch job.Status {
+		case model.JOB_STATUS_PENDING:
+			return job, nil
+		case model.JOB_STATUS_IN_PROGRESS:
+			if time.Since(time.Unix(0, job.LastActivityAt*int64(time.Millisecond))) < TEAM_DELETION_STALE_JOB_TIMEOUT {
+				return job, nil
+			}
+
+			// The job won't be run again since it's already been claimed
+			if result := <-a.Srv.Store.Job().UpdateStatusOptimistically(job.Id, model.JOB_STATUS_IN_PROGRESS, model.JOB_STATUS_ERROR); result.Err != nil {
+				return nil, result.Err
+			}
+		case model.JOB_STATUS_SUCCESS:
+			continue
+		}
+
+		if interrupted == nil || job.CreateAt > interrupted.CreateAt {
+			interrupted = job
+		}
+	}
+
+	progress := &model.TeamDeletionProgress{TeamId: team.Id}
+	if interrupted != nil {
+		progress = model.TeamDeletionProgressFromJobData(interrupted.Data)
+		mlog.Info("Resuming the permanent deletion of a team", mlog.String("team_id", team.Id), mlog.String("job_id", interrupted.Id), mlog.String("stage", progress.Stage))
+	}
+
+	data := map[string]string{}
+	progress.ToJobData(data)
+
+	return a.Jobs.CreateJob(model.JOB_TYPE_TEAM_DELETION, data)
+}
+
+// PermanentDeleteTeamWithProgress permanently deletes the team that the progress is for, along with its channels,
+// posts, files, webhooks, commands and members, starting from the stage and counts that the progress has reached. The
+// optional callback is called with the progress before each stage and each channel, and pauses the deletion by
+// returning false, after which it picks up where it stopped when it's called again with the same progress. Each
+// stage can be safely done again, so a deletion that was interrupted part way through one still converges.
+func (a *App) PermanentDeleteTeamWithProgress(progress *model.TeamDeletionProgress, callback func(progress *model.TeamDeletionProgress) bool) *model.AppError {
+	if progress.Stage == "" {
+		progress.Stage = model.TeamDeletionStages[0]
+	}
+
+	for !progress.IsDone() {
+		if callback != nil && !callback(progress) {
+			return nil
+		}
+
+		var err *model.AppError
+		switch progress.Stage {
+		case model.TEAM_DELETION_STAGE_CHANNELS:
+			var stopped bool
+			if stopped, err = a.permanentDeleteTeamChannels(progress, callback); err == nil && stopped {
+				return nil
+			}
+		case model.TEAM_DELETION_STAGE_WEBHOOKS:
+			if result := <-a.Srv.Store.Webhook().PermanentDeleteIncomingByTeam(progress.TeamId); result.Err != nil {
+				err = result.Err
+			} else if result := <-a.Srv.Store.Webhook().PermanentDeleteOutgoingByTeam(progress.TeamId); result.Err != nil {
+				err = result.Err
+			}
+		case model.TEAM_DELETION_STAGE_COMMANDS:
+			if result := <-a.Srv.Store.Command().PermanentDeleteByTeam(progress.TeamId); result.Err != nil {
+				err = result.Err
+			}
+		case model.TEAM_DELETION_STAGE_PREFERENCES:
+			err = a.permanentDeleteTeamPreferences(progress)
+		case model.TEAM_DELETION_STAGE_MEMBERS:
+			if result := <-a.Srv.Store.Team().RemoveAllMembersByTeam(progress.TeamId); result.Err != nil {
+				err = result.Err
+			}
+		case model.TEAM_DELETION_STAGE_VERIFY:
+			if err = a.verifyTeamDeletion(progress.TeamId); err != nil {
+				// Whatever was left behind, such as a channel made while the team was being deleted, is picked up by
+				// going through the stages again
+				progress.Stage = model.TeamDeletionStages[0]
+				return err
+			}
+		case model.TEAM_DELETION_STAGE_TEAM:
+			err = a.permanentDeleteTeamRecord(progress.TeamId)
+		default:
+			err = model.NewAppError("PermanentDeleteTeamWithProgress", "app.team.permanent_delete.stage.app_error", nil, "stage="+progress.Stage, http.StatusInternalServerError)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		progress.Stage = nextTeamDeletionStage(progress.Stage)
+	}
+
+	return nil
+}
+
+func nextTeamDeletionStage(stage string) string {
+	for i, s := range model.TeamDeletionStages {
+		if s == stage && i+1 < len(model.TeamDeletionStages) {
+			return model.TeamDeletionStages[i+1]
+		}
+	}
+
+	return model.TEAM_DELETION_STAGE_DONE
+}
+
+// permanentDeleteTeamChannels deletes the team's channels in batches, returning true if the callback stopped it. A
+// channel's row is deleted after everything in it, so anything left by an interrupted deletion is found again
+// through the channel.
+func (a *App) permanentDeleteTeamChannels(progress *model.TeamDeletionProgress, callback func(progress *model.TeamDeletionProgress) bool) (bool, *model.AppError) {
+	if progress.ChannelsTotal == 0 {
+		result := <-a.Srv.Store.Team().GetDataCounts(progress.TeamId)
+		if result.Err != nil {
+			return false, result.Err
+		}
+		progress.ChannelsTotal = result.Data.(map[string]int64)["Channels"]
+	}
+
+	for {
+		result := <-a.Srv.Store.Channel().GetBatchForTeam(progress.TeamId, TEAM_DELETION_CHANNEL_BATCH_SIZE)
+		if result.Err != nil {
+			return false, result.Err
+		}
+
+		channels := result.Data.([]*model.Channel)
+		if len(channels) == 0 {
+			break
+		}
+
+		for _, channel := range channels {
+			if callback != nil && !callback(progress) {
+				return true, nil
+			}
+
+			filesDeleted, err := a.permanentDeleteChannel(channel)
+			progress.FilesDeleted += filesDeleted
+			if err != nil {
+				return false, err
+			}
+			progress.ChannelsDeleted++
+			a.InvalidateCacheForChannel(channel)
+		}
+	}
+
+	// The names that the team's channels used to have are only found by the team
+	if result := <-a.Srv.Store.Channel().PermanentDeleteByTeam(progress.TeamId); result.Err != nil {
+		return false, result.Err
+	}
+
+	return false, nil
+}
+
+// permanentDeleteTeamPreferences deletes the preferences for the channels and posts that no longer exist, which
+// includes those of the team.
+func (a *App) permanentDeleteTeamPreferences(progress *model.TeamDeletionProgress) *model.AppError {
+	for _, cleanup := range []func(limit int64) store.StoreChannel{
+		a.Srv.Store.Preference().CleanupChannelsBatch,
+		a.Srv.Store.Preference().CleanupFlagsBatch,
+	} {
+		for {
+			result := <-cleanup(TEAM_DELETION_PREFERENCE_BATCH_SIZE)
+			if result.Err != nil {
+				return result.Err
+			}
+
+			deleted := result.Data.(int64)
+			progress.PreferencesDeleted += deleted
+			if deleted < TEAM_DELETION_PREFERENCE_BATCH_SIZE {
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// verifyTeamDeletion returns an error listing what's left of the team, other than the team itself.
+func (a *App) verifyTeamDeletion(teamId string) *model.AppError {
+	result := <-a.Srv.Store.Team().GetDataCounts(teamId)
+	if result.Err != nil {
+		return result.Err
+	}
+
+	var remaining []string
+	for table, count := range result.Data.(map[string]int64) {
+		if count > 0 {
+			remaining = append(remaining, fmt.Sprintf("%v=%v", table, count))
+		}
+	}
+
+	if len(remaining) > 0 {
+		sort.Strings(remaining)
+		return model.NewAppError("verifyTeamDeletion", "app.team.permanent_delete.verify.app_error", nil, "team_id="+teamId+", "+strings.Join(remaining, ", "), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+// permanentDeleteTeamRecord deletes the team itself, which has already gone if an earlier run got this far.
+func (a *App) permanentDeleteTeamRecord(teamId string) *model.AppError {
+	result := <-a.Srv.Store.Team().Get(teamId)
+	if result.Err != nil {
+		if result.Err.StatusCode == http.StatusNotFound {
+			return nil
+		}
+		return result.Err
+	}
+	team := result.Data.(*model.Team)
+
+	if result := <-a.Srv.Store.Team().PermanentDelete(team.Id); result.Err != nil {
+		return result.Err
+	}
+
+	a.sendTeamEvent(team, model.WEBSOCKET_EVENT_DELETE_TEAM)
+
+	return nil
+}
@@ -0,0 +1,258 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+type teamDeletionTestData struct {
+	team       *model.Team
+	channels   []*model.Channel
+	posts      []*model.Post
+	files      []*model.FileInfo
+	incoming   *model.IncomingWebhook
+	outgoing   *model.OutgoingWebhook
+	command    *model.Command
+	preference model.Preference
+}
+
+func createTeamDeletionTestData(t *testing.T, th *TestHelper) *teamDeletionTestData {
+	data := &teamDeletionTestData{team: th.CreateTeam()}
+	th.LinkUserToTeam(th.BasicUser, data.team)
+	th.LinkUserToTeam(th.BasicUser2, data.team)
+
+	for _, channelType := range []string{model.CHANNEL_OPEN, model.CHANNEL_PRIVATE, model.CHANNEL_OPEN} {
+		channel := th.createChannel(data.team, channelType)
+		th.AddUserToChannel(th.BasicUser2, channel)
+
+		file, err := th.App.DoUploadFile(time.Now(), data.team.Id, channel.Id, th.BasicUser.Id, "file.txt", []byte("data"))
+		require.Nil(t, err)
+
+		post, err := th.App.CreatePost(&model.Post{
+			ChannelId: channel.Id,
+			UserId:    th.BasicUser.Id,
+			Message:   "message",
+			FileIds:   []string{file.Id},
+		}, channel, false)
+		require.Nil(t, err)
+
+		data.channels = append(data.channels, channel)
+		data.posts = append(data.posts, post, th.CreatePost(channel))
+		data.files = append(data.files, file)
+	}
+	require.Nil(t, th.App.DeleteChannel(data.channels[2], th.BasicUser.Id), "archived channels should be deleted too")
+
+	var err *model.AppError
+	data.incoming, err = th.App.CreateIncomingWebhookForChannel(th.BasicUser.Id, data.channels[0], &model.IncomingWebhook{ChannelId: data.channels[0].Id})
+	require.Nil(t, err)
+	data.outgoing, err = th.App.CreateOutgoingWebhook(&model.OutgoingWebhook{
+		TeamId:       data.team.Id,
+		CreatorId:    th.BasicUser.Id,
+		TriggerWords: []string{"trigger"},
+		CallbackURLs: []string{"http://foo"},
+	})
+	require.Nil(t, err)
+	data.command, err = th.App.CreateCommand(&model.Command{
+		CreatorId: th.BasicUser.Id,
+		TeamId:    data.team.Id,
+		Trigger:   "foo",
+		URL:       "http://foo",
+		Method:    model.COMMAND_METHOD_POST,
+	})
+	require.Nil(t, err)
+
+	data.preference = model.Preference{UserId: th.BasicUser2.Id, Category: model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, Name: data.channels[1].Id, Value: "true"}
+	require.Nil(t, th.App.UpdatePreferences(th.BasicUser2.Id, model.Preferences{data.preference}))
+
+	return data
+}
+
+func assertTeamDeletionComplete(t *testing.T, th *TestHelper, data *teamDeletionTestData) {
+	result := <-th.App.Srv.Store.Team().GetDataCounts(data.team.Id)
+	require.Nil(t, result.Err)
+	for table, count := range result.Data.(map[string]int64) {
+		assert.Equal(t, int64(0), count, "nothing should be left in "+table)
+	}
+
+	_, err := th.App.GetTeam(data.team.Id)
+	assert.NotNil(t, err, "the team should be deleted")
+
+	for _, channel := range data.channels {
+		th.App.InvalidateCacheForChannel(channel)
+		_, err = th.App.GetChannel(channel.Id)
+		assert.NotNil(t, err, "the team's channels should be deleted")
+
+		members, err := th.App.GetChannelMembersPage(channel.Id, 0, 100)
+		require.Nil(t, err)
+		assert.Empty(t, *members, "the channels' members should be deleted")
+	}
+
+	for _, post := range data.posts {
+		_, err = th.App.GetSinglePost(post.Id)
+		assert.NotNil(t, err, "the team's posts should be deleted")
+	}
+
+	for _, file := range data.files {
+		_, err = th.App.GetFileInfo(file.Id)
+		assert.NotNil(t, err, "the team's file infos should be deleted")
+
+		_, err = th.App.ReadFile(file.Path)
+		assert.NotNil(t, err, "the team's files should be removed from the file store")
+	}
+
+	_, err = th.App.GetIncomingWebhook(data.incoming.Id)
+	assert.NotNil(t, err, "the team's incoming webhooks should be deleted")
+	_, err = th.App.GetOutgoingWebhook(data.outgoing.Id)
+	assert.NotNil(t, err, "the team's outgoing webhooks should be deleted")
+	_, err = th.App.GetCommand(data.command.Id)
+	assert.NotNil(t, err, "the team's commands should be deleted")
+
+	_, err = th.App.GetPreferenceByCategoryAndNameForUser(data.preference.UserId, data.preference.Category, data.preference.Name)
+	assert.NotNil(t, err, "preferences for the team's channels should be deleted")
+
+	teams, err := th.App.GetTeamsForUser(th.BasicUser2.Id)
+	require.Nil(t, err)
+	for _, team := range teams {
+		assert.NotEqual(t, data.team.Id, team.Id, "the team's members should be removed")
+	}
+}
+
+func TestPermanentDeleteTeamWithProgress(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		cfg.ServiceSettings.EnableIncomingWebhooks = true
+		cfg.ServiceSettings.EnableOutgoingWebhooks = true
+		*cfg.ServiceSettings.EnableCommands = true
+	})
+
+	t.Run("interrupted and resumed", func(t *testing.T) {
+		data := createTeamDeletionTestData(t, th)
+
+		job, err := th.App.MarkTeamForPermanentDeletion(data.team)
+		require.Nil(t, err)
+		assert.Equal(t, model.JOB_TYPE_TEAM_DELETION, job.Type)
+		assert.Equal(t, data.team.Id, job.Data["team_id"])
+
+		team, err := th.App.GetTeam(data.team.Id)
+		require.Nil(t, err)
+		assert.NotEqual(t, int64(0), team.DeleteAt, "the team should be archived while it's deleted")
+
+		again, err := th.App.MarkTeamForPermanentDeletion(team)
+		require.Nil(t, err)
+		assert.Equal(t, job.Id, again.Id, "the pending job should be reused")
+
+		// The job is stopped part way through deleting the channels, as it would be by the server shutting down
+		claimed, err := th.App.Jobs.ClaimJob(job)
+		require.Nil(t, err)
+		require.True(t, claimed)
+
+		progress := model.TeamDeletionProgressFromJobData(job.Data)
+		err = th.App.PermanentDeleteTeamWithProgress(progress, func(progress *model.TeamDeletionProgress) bool {
+			return progress.ChannelsDeleted < 2
+		})
+		require.Nil(t, err)
+		assert.Equal(t, model.TEAM_DELETION_STAGE_CHANNELS, progress.Stage)
+		assert.Equal(t, int64(2), progress.ChannelsDeleted)
+		assert.True(t, progress.ChannelsTotal >= int64(len(data.channels)))
+		assert.True(t, progress.FilesDeleted <= 2)
+		assert.True(t, progress.Percent() > 0 && progress.Percent() < model.TEAM_DELETION_CHANNELS_PERCENT)
+
+		progress.ToJobData(job.Data)
+		require.Nil(t, th.App.Jobs.SetJobProgress(job, progress.Percent()))
+		require.Nil(t, th.App.Jobs.SetJobCanceled(job))
+
+		_, err = th.App.GetTeam(data.team.Id)
+		require.Nil(t, err, "the team shouldn't be deleted until everything on it is")
+
+		// Running the deletion again resumes it from the checkpoint
+		resumed, err := th.App.MarkTeamForPermanentDeletion(team)
+		require.Nil(t, err)
+		assert.NotEqual(t, job.Id, resumed.Id)
+		assert.Equal(t, model.TEAM_DELETION_STAGE_CHANNELS, resumed.Data["stage"])
+		assert.Equal(t, "2", resumed.Data["channels_deleted"])
+
+		progress = model.TeamDeletionProgressFromJobData(resumed.Data)
+		var stages []string
+		err = th.App.PermanentDeleteTeamWithProgress(progress, func(progress *model.TeamDeletionProgress) bool {
+			if len(stages) == 0 || stages[len(stages)-1] != progress.Stage {
+				stages = append(stages, progress.Stage)
+			}
+			return true
+		})
+		require.Nil(t, err)
+		assert.True(t, progress.IsDone())
+		assert.Equal(t, model.TeamDeletionStages, stages)
+		assert.Equal(t, progress.ChannelsTotal, progress.ChannelsDeleted)
+		assert.Equal(t, int64(len(data.files)), progress.FilesDeleted)
+		assert.Equal(t, int64(100), progress.Percent())
+
+		assertTeamDeletionComplete(t, th, data)
+
+		// Deleting a team that's already gone does nothing
+		require.Nil(t, th.App.PermanentDeleteTeamWithProgress(&model.TeamDeletionProgress{TeamId: data.team.Id}, nil))
+	})
+
+	t.Run("stalled job", func(t *testing.T) {
+		team := th.CreateTeam()
+		require.Nil(t, th.App.SoftDeleteTeam(team.Id))
+		team, err := th.App.GetTeam(team.Id)
+		require.Nil(t, err)
+
+		// A job that was running when the server stopped is left in progress
+		stalled := &model.Job{
+			Id:             model.NewId(),
+			Type:           model.JOB_TYPE_TEAM_DELETION,
+			CreateAt:       model.GetMillis() - 2*int64(TEAM_DELETION_STALE_JOB_TIMEOUT/time.Millisecond),
+			LastActivityAt: model.GetMillis() - 2*int64(TEAM_DELETION_STALE_JOB_TIMEOUT/time.Millisecond),
+			Status:         model.JOB_STATUS_IN_PROGRESS,
+			Data:           map[string]string{},
+		}
+		(&model.TeamDeletionProgress{TeamId: team.Id, Stage: model.TEAM_DELETION_STAGE_COMMANDS}).ToJobData(stalled.Data)
+		require.Nil(t, (<-th.App.Srv.Store.Job().Save(stalled)).Err)
+
+		resumed, err := th.App.MarkTeamForPermanentDeletion(team)
+		require.Nil(t, err)
+		assert.NotEqual(t, stalled.Id, resumed.Id)
+		assert.Equal(t, model.TEAM_DELETION_STAGE_COMMANDS, resumed.Data["stage"])
+
+		stalled, err = th.App.Jobs.GetJob(stalled.Id)
+		require.Nil(t, err)
+		assert.Equal(t, model.JOB_STATUS_ERROR, stalled.Status)
+	})
+
+	t.Run("verification", func(t *testing.T) {
+		team := th.CreateTeam()
+		th.LinkUserToTeam(th.BasicUser, team)
+		channel := th.CreateChannel(team)
+
+		// Something left behind, like a channel made while the team was being deleted, fails the verification
+		progress := &model.TeamDeletionProgress{TeamId: team.Id, Stage: model.TEAM_DELETION_STAGE_VERIFY}
+		err := th.App.PermanentDeleteTeamWithProgress(progress, nil)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.team.permanent_delete.verify.app_error", err.Id)
+		assert.Contains(t, err.DetailedError, "Channels=")
+		assert.Equal(t, model.TeamDeletionStages[0], progress.Stage, "the deletion should start over")
+
+		_, err = th.App.GetTeam(team.Id)
+		require.Nil(t, err)
+
+		require.Nil(t, th.App.PermanentDeleteTeamWithProgress(progress, nil))
+		assert.True(t, progress.IsDone())
+
+		th.App.InvalidateCacheForChannel(channel)
+		_, err = th.App.GetChannel(channel.Id)
+		assert.NotNil(t, err)
+		_, err = th.App.GetTeam(team.Id)
+		assert.NotNil(t, err)
+	})
+}
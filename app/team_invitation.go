@@ -0,0 +1,146 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+// queueTeamInvitation queues the email for an invitation and records whether it was sent once it's been tried.
+func (a *App) queueTeamInvitation(invitation *model.TeamInvitation, priority int) {
+	a.queueMail(priority, func() *model.AppError {
+		return a.sendTeamInvitation(invitation)
+	}, func(err *model.AppError) {
+		status, reason := model.TEAM_INVITATION_STATUS_SENT, ""
+		if err != nil {
+			mlog.Warn(fmt.Sprintf("Unable to send invitation email=%v err=%v", invitation.Email, err.Error()), mlog.String("invitation_id", invitation.Id))
+			status, reason = model.TEAM_INVITATION_STATUS_FAILED, err.Message
+		}
+
+		if result := <-a.Srv.Store.TeamInvitation().UpdateStatus(invitation.Id, status, reason, model.GetMillis()); result.Err != nil {
+			mlog.Error(fmt.Sprintf("Failed to update the status of an invitation: %v", result.Err.Error()), mlog.String("invitation_id", invitation.Id))
+		}
+	})
+}
+
+func (a *App) sendTeamInvitation(invitation *model.TeamInvitation) *model.AppError {
+	team, err := a.GetTeam(invitation.TeamId)
+	if err != nil {
+		return err
+	}
+
+	sender, err := a.GetUser(invitation.SenderId)
+	if err != nil {
+		return err
+	}
+
+	channels := make([]*model.Channel, 0, len(invitation.Channels))
+	for _, channelId := range invitation.Channels {
+		channel, err := a.GetChannel(channelId)
+		if err != nil {
+			return err
+		}
+		channels = append(channels, channel)
+	}
+
+	senderName := sender.GetDisplayName(*a.Config().TeamSettings.TeammateNameDisplay)
+
+	return a.SendInviteEmail(team, channels, senderName, invitation.ToInvite(), a.GetSiteURL())
+}
+
+func (a *App) GetTeamInvitation(invitationId string) (*model.TeamInvitation, *model.AppError) {
+	result := <-a.Srv.Store.TeamInvitation().Get(invitationId)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.TeamInvitation), nil
+}
+
+// GetTeamInvitations returns a page of the invitations that were sent for a team, newest first.
+func (a *App) GetTeamInvitations(teamId string, page, perPage int) ([]*model.TeamInvitation, *model.AppError) {
+	result := <-a.Srv.Store.TeamInvitation().GetForTeam(teamId, page*perPage, perPage)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.([]*model.TeamInvitation), nil
+}
+
+// ResendTeamInvitation queues an invitation that failed to be sent again, ahead of the invitations that haven't been
+// sent for the first time yet.
+func (a *App) ResendTeamInvitation(invitation *model.TeamInvitation) (*model.TeamInvitation, *model.AppError) {
+	if invitation.Status != model.TEAM_INVITATION_STATUS_FAILED {
+		return nil, model.NewAppError("ResendTeamInvitation", "app.team_invitation.resend.not_failed.app_error", nil, "invitation_id="+invitation.Id+", status="+invitation.Status, http.StatusBadRequest)
+	}
+
+	team, err := a.GetTeam(invitation.TeamId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.checkTeamInviteAllowed(team, invitation.ToInvite()); err != nil {
+		return nil, err
+	}
+
+	updateAt := model.GetMillis()
+	if result := <-a.Srv.Store.TeamInvitation().UpdateStatus(invitation.Id, model.TEAM_INVITATION_STATUS_QUEUED, "", updateAt); result.Err != nil {
+		return nil, result.Err
+	}
+
+	resent := *invitation
+	resent.Status = model.TEAM_INVITATION_STATUS_QUEUED
+	resent.Reason = ""
+	resent.UpdateAt = updateAt
+
+	a.queueTeamInvitation(&resent, MAIL_PRIORITY_HIGH)
+
+	return &resent, nil
+}
+
+// CheckEmailBounceSecret returns an error unless bounce notifications are turned on and the secret is the one that
+// they're sent with.
+func (a *App) CheckEmailBounceSecret(secret string) *model.AppError {
+	expected := *a.Config().EmailSettings.BounceWebhookSecret
+	if expected == "" {
+		return model.NewAppError("CheckEmailBounceSecret", "app.team_invitation.bounce.disabled.app_error", nil, "", http.StatusNotImplemented)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(expected)) != 1 {
+		return model.NewAppError("CheckEmailBounceSecret", "app.team_invitation.bounce.invalid_secret.app_error", nil, "", http.StatusUnauthorized)
+	}
+
+	return nil
+}
+
+// HandleEmailBounce marks the invitations that were sent to the recipient of an email that bounced as failed.
+func (a *App) HandleEmailBounce(bounce *model.EmailBounce) *model.AppError {
+	email := strings.ToLower(strings.TrimSpace(bounce.Recipient))
+	if !model.IsValidEmail(email) {
+		return model.NewAppError("HandleEmailBounce", "app.team_invitation.bounce.recipient.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	reason := strings.TrimSpace(bounce.Reason)
+	if reason == "" {
+		reason = utils.T("app.team_invitation.bounce.default_reason")
+	}
+
+	result := <-a.Srv.Store.TeamInvitation().MarkBounced(email, reason, model.GetMillis())
+	if result.Err != nil {
+		return result.Err
+	}
+
+	if count := result.Data.(int64); count > 0 {
+		mlog.Info(fmt.Sprintf("Marked %v invitations as failed after an email bounced", count))
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestTeamInvitationBounce(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.EmailSettings.QueuedEmailsPerMinute = 0 })
+
+	email := strings.ToLower(model.NewId()) + "success+test@example.com"
+	results, err := th.App.SendTeamInvites(th.BasicTeam.Id, []*model.Invite{
+		{Email: email, Channels: []string{th.BasicChannel.Id}},
+	}, th.BasicUser.Id)
+	require.Nil(t, err)
+	require.Len(t, results, 1)
+	require.Nil(t, results[0].Error)
+	require.NotEmpty(t, results[0].InvitationId)
+
+	invitationId := results[0].InvitationId
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		invitation, err := th.App.GetTeamInvitation(invitationId)
+		require.Nil(t, err)
+		if invitation.Status != model.TEAM_INVITATION_STATUS_QUEUED {
+			break
+		}
+		require.True(t, time.Since(start) < 5*time.Second, "the invitation should have been sent")
+	}
+
+	// Whether the email could actually be sent depends on the SMTP server that the tests are run with
+	result := <-th.App.Srv.Store.TeamInvitation().UpdateStatus(invitationId, model.TEAM_INVITATION_STATUS_SENT, "", model.GetMillis())
+	require.Nil(t, result.Err)
+
+	invitation, err := th.App.GetTeamInvitation(invitationId)
+	require.Nil(t, err)
+
+	_, err = th.App.ResendTeamInvitation(invitation)
+	require.NotNil(t, err, "an invitation that was sent shouldn't be resent")
+	assert.Equal(t, http.StatusBadRequest, err.StatusCode)
+
+	err = th.App.HandleEmailBounce(&model.EmailBounce{Recipient: " " + strings.ToUpper(email) + " ", Reason: "Mailbox not found"})
+	require.Nil(t, err)
+
+	invitations, err := th.App.GetTeamInvitations(th.BasicTeam.Id, 0, 10)
+	require.Nil(t, err)
+	require.Len(t, invitations, 1)
+	assert.Equal(t, model.TEAM_INVITATION_STATUS_FAILED, invitations[0].Status)
+	assert.Equal(t, "Mailbox not found", invitations[0].Reason)
+
+	err = th.App.HandleEmailBounce(&model.EmailBounce{Recipient: "not an email"})
+	require.NotNil(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.StatusCode)
+
+	resent, err := th.App.ResendTeamInvitation(invitations[0])
+	require.Nil(t, err)
+	assert.Equal(t, model.TEAM_INVITATION_STATUS_QUEUED, resent.Status)
+	assert.Equal(t, "", resent.Reason)
+}
+
+func TestCheckEmailBounceSecret(t *testing.T) {
+	th := Setup()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.EmailSettings.BounceWebhookSecret = "" })
+
+	err := th.App.CheckEmailBounceSecret("")
+	require.NotNil(t, err)
+	assert.Equal(t, http.StatusNotImplemented, err.StatusCode)
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.EmailSettings.BounceWebhookSecret = "secret" })
+
+	assert.Nil(t, th.App.CheckEmailBounceSecret("secret"))
+
+	err = th.App.CheckEmailBounceSecret("wrong")
+	require.NotNil(t, err)
+	assert.Equal(t, http.StatusUnauthorized, err.StatusCode)
+}
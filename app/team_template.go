@@ -0,0 +1,435 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const TEAM_TEMPLATE_WEBHOOKS_PER_PAGE = 200
+
+// teamTemplateApplication keeps track of what applying a team template has created so far, so that it can be
+// removed again if the rest of the template can't be applied.
+type teamTemplateApplication struct {
+	teamId string
+
+	// createdTeam is only set if the team was created by the template, in which case everything else goes with it
+	createdTeam *model.Team
+
+	channels   []*model.Channel
+	memberIds  []string
+	webhookIds []string
+
+	// previousDefaultChannels is only set if the template replaced the default channels of an existing team
+	previousDefaultChannels []string
+}
+
+func (a *App) SaveTeamTemplate(template *model.TeamTemplate) (*model.TeamTemplate, *model.AppError) {
+	template.Id = ""
+
+	result := <-a.Srv.Store.TeamTemplate().Save(template)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.TeamTemplate), nil
+}
+
+func (a *App) UpdateTeamTemplate(template *model.TeamTemplate) (*model.TeamTemplate, *model.AppError) {
+	oldTemplate, err := a.GetTeamTemplate(template.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	template.CreatorId = oldTemplate.CreatorId
+	template.CreateAt = oldTemplate.CreateAt
+
+	result := <-a.Srv.Store.TeamTemplate().Update(template)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.TeamTemplate), nil
+}
+
+func (a *App) GetTeamTemplate(templateId string) (*model.TeamTemplate, *model.AppError) {
+	result := <-a.Srv.Store.TeamTemplate().Get(templateId)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.TeamTemplate), nil
+}
+
+func (a *App) GetTeamTemplateByName(name string) (*model.TeamTemplate, *model.AppError) {
+	result := <-a.Srv.Store.TeamTemplate().GetByName(name)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.TeamTemplate), nil
+}
+
+func (a *App) GetTeamTemplates(page int, perPage int) ([]*model.TeamTemplate, *model.AppError) {
+	result := <-a.Srv.Store.TeamTemplate().GetAll(page*perPage, perPage)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.([]*model.TeamTemplate), nil
+}
+
+func (a *App) DeleteTeamTemplate(templateId string) *model.AppError {
+	if _, err := a.GetTeamTemplate(templateId); err != nil {
+		return err
+	}
+
+	if result := <-a.Srv.Store.TeamTemplate().Delete(templateId); result.Err != nil {
+		return result.Err
+	}
+
+	return nil
+}
+
+// CreateTeamFromSavedTemplate creates a team from a saved template like CreateTeamFromTemplate does, using the name
+// and display name of the given team, if it has them, instead of those in the template.
+func (a *App) CreateTeamFromSavedTemplate(templateId string, team *model.Team, creatorId string, update bool) (*model.Team, *model.AppError) {
+	template, err := a.GetTeamTemplate(templateId)
+	if err != nil {
+		return nil, err
+	}
+
+	if template.Team == nil {
+		template.Team = &model.Team{}
+	}
+
+	if team != nil && team.Name != "" {
+		template.Team.Name = team.Name
+	}
+	if team != nil && team.DisplayName != "" {
+		template.Team.DisplayName = team.DisplayName
+	}
+
+	return a.CreateTeamFromTemplate(template, creatorId, update)
+}
+
+// CreateTeamFromTemplate creates the team described by the template along with its channels, the channels that its
+// new members join, its admins and its incoming webhooks. The webhooks are created by creatorId, or by the first of
+// the admins if there's no creator. There's no transaction that spans all of them, so if any part of the template
+// can't be applied, everything that it created is removed again instead, so that it can be fixed and applied again.
+//
+// With update, a team with the template's name that already exists is brought in line with the template rather than
+// being an error: its settings and the names, headers and purposes of its channels are updated, and the channels,
+// admins and webhooks that it doesn't have yet are added, so applying the same template twice changes nothing the
+// second time. A channel's type isn't changed, and updates to what already existed aren't undone if a later part of
+// the template fails.
+func (a *App) CreateTeamFromTemplate(template *model.TeamTemplate, creatorId string, update bool) (*model.Team, *model.AppError) {
+	if template.Team == nil {
+		return nil, model.NewAppError("CreateTeamFromTemplate", "app.team_template.no_team.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if err := template.IsValidContent(); err != nil {
+		return nil, err
+	}
+
+	applied := &teamTemplateApplication{}
+
+	team, err := a.applyTeamTemplate(template, creatorId, update, applied)
+	if err != nil {
+		a.rollBackTeamTemplate(applied)
+		return nil, err
+	}
+
+	return team, nil
+}
+
+func (a *App) applyTeamTemplate(template *model.TeamTemplate, creatorId string, update bool, applied *teamTemplateApplication) (*model.Team, *model.AppError) {
+	team, err := a.applyTeamTemplateTeam(template, update, applied)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, templateChannel := range template.Channels {
+		if err := a.applyTeamTemplateChannel(team, templateChannel, creatorId, applied); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(template.DefaultChannels) > 0 {
+		if err := a.applyTeamTemplateDefaultChannels(team, template.DefaultChannels, applied); err != nil {
+			return nil, err
+		}
+	}
+
+	admins := make([]*model.User, 0, len(template.AdminEmails))
+	for _, email := range template.AdminEmails {
+		admin, err := a.applyTeamTemplateAdmin(team, email, applied)
+		if err != nil {
+			return nil, err
+		}
+		admins = append(admins, admin)
+	}
+
+	if len(template.IncomingWebhooks) > 0 {
+		if creatorId == "" {
+			if len(admins) == 0 {
+				return nil, model.NewAppError("CreateTeamFromTemplate", "app.team_template.webhook_creator.app_error", nil, "", http.StatusBadRequest)
+			}
+			creatorId = admins[0].Id
+		}
+
+		if err := a.applyTeamTemplateWebhooks(team, template.IncomingWebhooks, creatorId, applied); err != nil {
+			return nil, err
+		}
+	}
+
+	return team, nil
+}
+
+func (a *App) applyTeamTemplateTeam(template *model.TeamTemplate, update bool, applied *teamTemplateApplication) (*model.Team, *model.AppError) {
+	team, err := a.GetTeamByName(template.Team.Name)
+	if err == nil {
+		if !update {
+			return nil, model.NewAppError("CreateTeamFromTemplate", "app.team_template.team_exists.app_error", map[string]interface{}{"Name": team.Name}, "", http.StatusBadRequest)
+		}
+		applied.teamId = team.Id
+
+		team.DisplayName = template.Team.DisplayName
+		team.Description = template.Team.Description
+		team.CompanyName = template.Team.CompanyName
+		team.AllowedDomains = template.Team.AllowedDomains
+		team.AllowOpenInvite = template.Team.AllowOpenInvite
+		team.WelcomeMessage = template.Team.WelcomeMessage
+
+		return a.UpdateTeam(team)
+	}
+
+	team = &model.Team{
+		Name:            template.Team.Name,
+		DisplayName:     template.Team.DisplayName,
+		Description:     template.Team.Description,
+		Email:           template.Team.Email,
+		Type:            template.Team.Type,
+		CompanyName:     template.Team.CompanyName,
+		AllowedDomains:  template.Team.AllowedDomains,
+		AllowOpenInvite: template.Team.AllowOpenInvite,
+		WelcomeMessage:  template.Team.WelcomeMessage,
+	}
+
+	if team.Type == "" {
+		team.Type = model.TEAM_OPEN
+	}
+
+	// The first admin owns the team, which makes them an admin of the channels they join on it
+	if team.Email == "" && len(template.AdminEmails) > 0 {
+		team.Email = template.AdminEmails[0]
+	}
+
+	if team, err = a.CreateTeam(team); err != nil {
+		return nil, err
+	}
+	applied.teamId = team.Id
+	applied.createdTeam = team
+
+	return team, nil
+}
+
+func (a *App) applyTeamTemplateChannel(team *model.Team, templateChannel *model.TeamTemplateChannel, creatorId string, applied *teamTemplateApplication) *model.AppError {
+	channel, err := a.getTeamTemplateChannel(team.Id, templateChannel.Name)
+	if err != nil {
+		return err
+	}
+
+	if channel != nil {
+		if channel.DisplayName == templateChannel.DisplayName && channel.Header == templateChannel.Header && channel.Purpose == templateChannel.Purpose {
+			return nil
+		}
+
+		channel.DisplayName = templateChannel.DisplayName
+		channel.Header = templateChannel.Header
+		channel.Purpose = templateChannel.Purpose
+
+		_, err := a.UpdateChannel(channel)
+		return err
+	}
+
+	channel, err = a.CreateChannel(&model.Channel{
+		TeamId:      team.Id,
+		Name:        templateChannel.Name,
+		DisplayName: templateChannel.DisplayName,
+		Type:        templateChannel.Type,
+		Header:      templateChannel.Header,
+		Purpose:     templateChannel.Purpose,
+		CreatorId:   creatorId,
+	}, false)
+	if err != nil {
+		return err
+	}
+	applied.channels = append(applied.channels, channel)
+
+	return nil
+}
+
+// getTeamTemplateChannel returns the channel on the team with the name, or nil if there isn't one. Unlike
+// GetChannelByName, channels that used to have the name aren't returned, since they aren't what the template meant.
+func (a *App) getTeamTemplateChannel(teamId string, name string) (*model.Channel, *model.AppError) {
+	result := <-a.Srv.Store.Channel().GetByName(teamId, name, false)
+	if result.Err != nil {
+		if result.Err.Id == "store.sql_channel.get_by_name.missing.app_error" {
+			return nil, nil
+		}
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.Channel), nil
+}
+
+func (a *App) applyTeamTemplateDefaultChannels(team *model.Team, names []string, applied *teamTemplateApplication) *model.AppError {
+	channelIds := make([]string, 0, len(names))
+	for _, name := range names {
+		channel, err := a.getTeamTemplateChannel(team.Id, name)
+		if err != nil {
+			return err
+		}
+
+		if channel == nil {
+			return model.NewAppError("CreateTeamFromTemplate", "app.team_template.channel_not_found.app_error", map[string]interface{}{"Name": name}, "team_id="+team.Id, http.StatusBadRequest)
+		}
+		channelIds = append(channelIds, channel.Id)
+	}
+
+	previous, err := a.GetTeamDefaultChannels(team.Id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := a.UpdateTeamDefaultChannels(team.Id, channelIds); err != nil {
+		return err
+	}
+
+	if applied.createdTeam == nil {
+		applied.previousDefaultChannels = previous
+	}
+
+	return nil
+}
+
+func (a *App) applyTeamTemplateAdmin(team *model.Team, email string, applied *teamTemplateApplication) (*model.User, *model.AppError) {
+	user, err := a.GetUserByEmail(email)
+	if err != nil {
+		return nil, model.NewAppError("CreateTeamFromTemplate", "app.team_template.admin_not_found.app_error", map[string]interface{}{"Email": email}, err.Error(), http.StatusBadRequest)
+	}
+
+	member, err := a.GetTeamMember(team.Id, user.Id)
+	if err != nil || member.DeleteAt != 0 {
+		if err := a.JoinUserToTeam(team, user, ""); err != nil {
+			return nil, err
+		}
+		applied.memberIds = append(applied.memberIds, user.Id)
+
+		if member, err = a.GetTeamMember(team.Id, user.Id); err != nil {
+			return nil, err
+		}
+	}
+
+	if !model.IsInRole(member.Roles, model.TEAM_ADMIN_ROLE_ID) {
+		if _, err := a.UpdateTeamMemberRoles(team.Id, user.Id, model.TEAM_USER_ROLE_ID+" "+model.TEAM_ADMIN_ROLE_ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+func (a *App) applyTeamTemplateWebhooks(team *model.Team, templateHooks []*model.TeamTemplateIncomingWebhook, creatorId string, applied *teamTemplateApplication) *model.AppError {
+	var existing []*model.IncomingWebhook
+	for page := 0; ; page++ {
+		hooks, err := a.GetIncomingWebhooksForTeamPage(team.Id, page, TEAM_TEMPLATE_WEBHOOKS_PER_PAGE)
+		if err != nil {
+			return err
+		}
+
+		existing = append(existing, hooks...)
+		if len(hooks) < TEAM_TEMPLATE_WEBHOOKS_PER_PAGE {
+			break
+		}
+	}
+
+	for _, templateHook := range templateHooks {
+		channel, err := a.getTeamTemplateChannel(team.Id, templateHook.ChannelName)
+		if err != nil {
+			return err
+		}
+
+		if channel == nil {
+			return model.NewAppError("CreateTeamFromTemplate", "app.team_template.channel_not_found.app_error", map[string]interface{}{"Name": templateHook.ChannelName}, "team_id="+team.Id, http.StatusBadRequest)
+		}
+
+		// Webhooks are told apart by their channel and display name, since their ids are only known once created
+		found := false
+		for _, hook := range existing {
+			if hook.ChannelId == channel.Id && hook.DisplayName == templateHook.DisplayName {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+
+		hook, err := a.CreateIncomingWebhookForChannel(creatorId, channel, &model.IncomingWebhook{
+			ChannelId:   channel.Id,
+			DisplayName: templateHook.DisplayName,
+			Description: templateHook.Description,
+			Username:    templateHook.Username,
+			IconURL:     templateHook.IconURL,
+		})
+		if err != nil {
+			return err
+		}
+		applied.webhookIds = append(applied.webhookIds, hook.Id)
+		existing = append(existing, hook)
+	}
+
+	return nil
+}
+
+// rollBackTeamTemplate removes what applying a team template created before it failed. Failing to remove any of it
+// is only logged, since what's left can still be removed by hand and the original error is the one that matters.
+func (a *App) rollBackTeamTemplate(applied *teamTemplateApplication) {
+	if applied.createdTeam != nil {
+		if err := a.PermanentDeleteTeam(applied.createdTeam); err != nil {
+			mlog.Error(fmt.Sprintf("Failed to remove a team created from a template, err=%v", err), mlog.String("team_id", applied.createdTeam.Id))
+		}
+		return
+	}
+
+	for _, hookId := range applied.webhookIds {
+		if err := a.DeleteIncomingWebhook(hookId); err != nil {
+			mlog.Error(fmt.Sprintf("Failed to remove a webhook created from a team template, err=%v", err), mlog.String("team_id", applied.teamId))
+		}
+	}
+
+	for _, userId := range applied.memberIds {
+		if err := a.RemoveUserFromTeam(applied.teamId, userId, ""); err != nil {
+			mlog.Error(fmt.Sprintf("Failed to remove a team member added from a team template, err=%v", err), mlog.String("team_id", applied.teamId), mlog.String("user_id", userId))
+		}
+	}
+
+	if applied.previousDefaultChannels != nil {
+		if result := <-a.Srv.Store.Team().UpdateDefaultChannels(applied.teamId, applied.previousDefaultChannels); result.Err != nil {
+			mlog.Error(fmt.Sprintf("Failed to restore the default channels of a team, err=%v", result.Err), mlog.String("team_id", applied.teamId))
+		}
+	}
+
+	for _, channel := range applied.channels {
+		if err := a.PermanentDeleteChannel(channel); err != nil {
+			mlog.Error(fmt.Sprintf("Failed to remove a channel created from a team template, err=%v", err), mlog.String("team_id", applied.teamId))
+		}
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func testTeamTemplate(th *TestHelper) *model.TeamTemplate {
+	return &model.TeamTemplate{
+		Team: &model.Team{
+			Name:        "project" + model.NewId(),
+			DisplayName: "Project",
+			Description: "Working on the project",
+			Type:        model.TEAM_INVITE,
+		},
+		Channels: []*model.TeamTemplateChannel{
+			{Name: "standup", DisplayName: "Standup", Type: model.CHANNEL_OPEN, Header: "Every day at 9"},
+			{Name: "leads", DisplayName: "Leads", Type: model.CHANNEL_PRIVATE, Purpose: "For the leads"},
+			{Name: model.DEFAULT_CHANNEL, DisplayName: "Town Square", Type: model.CHANNEL_OPEN, Header: "Welcome to the project"},
+		},
+		DefaultChannels:  []string{"standup"},
+		AdminEmails:      []string{th.BasicUser.Email},
+		IncomingWebhooks: []*model.TeamTemplateIncomingWebhook{{ChannelName: "standup", DisplayName: "Reports"}},
+	}
+}
+
+func teamTemplateChannel(t *testing.T, th *TestHelper, teamId string, name string) *model.Channel {
+	t.Helper()
+
+	channel, err := th.App.getTeamTemplateChannel(teamId, name)
+	require.Nil(t, err)
+	return channel
+}
+
+func teamTemplateWebhooks(t *testing.T, th *TestHelper, teamId string) []*model.IncomingWebhook {
+	t.Helper()
+
+	hooks, err := th.App.GetIncomingWebhooksForTeamPage(teamId, 0, 100)
+	require.Nil(t, err)
+	return hooks
+}
+
+func TestCreateTeamFromTemplate(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { cfg.ServiceSettings.EnableIncomingWebhooks = true })
+
+	template := testTeamTemplate(th)
+
+	team, err := th.App.CreateTeamFromTemplate(template, th.BasicUser2.Id, false)
+	require.Nil(t, err)
+	assert.Equal(t, template.Team.Name, team.Name)
+	assert.Equal(t, "Working on the project", team.Description)
+	assert.Equal(t, model.TEAM_INVITE, team.Type)
+	assert.Equal(t, th.BasicUser.Email, team.Email)
+
+	standup := teamTemplateChannel(t, th, team.Id, "standup")
+	require.NotNil(t, standup)
+	assert.Equal(t, "Every day at 9", standup.Header)
+	assert.Equal(t, model.CHANNEL_OPEN, standup.Type)
+
+	leads := teamTemplateChannel(t, th, team.Id, "leads")
+	require.NotNil(t, leads)
+	assert.Equal(t, model.CHANNEL_PRIVATE, leads.Type)
+
+	townSquare := teamTemplateChannel(t, th, team.Id, model.DEFAULT_CHANNEL)
+	require.NotNil(t, townSquare)
+	assert.Equal(t, "Welcome to the project", townSquare.Header)
+
+	defaultChannels, err := th.App.GetTeamDefaultChannels(team.Id)
+	require.Nil(t, err)
+	assert.Equal(t, []string{standup.Id}, defaultChannels)
+
+	member, err := th.App.GetTeamMember(team.Id, th.BasicUser.Id)
+	require.Nil(t, err)
+	assert.True(t, model.IsInRole(member.Roles, model.TEAM_ADMIN_ROLE_ID))
+
+	_, err = th.App.GetChannelMember(standup.Id, th.BasicUser.Id)
+	assert.Nil(t, err, "the admin should have joined the default channels")
+
+	hooks := teamTemplateWebhooks(t, th, team.Id)
+	require.Len(t, hooks, 1)
+	assert.Equal(t, "Reports", hooks[0].DisplayName)
+	assert.Equal(t, standup.Id, hooks[0].ChannelId)
+	assert.Equal(t, th.BasicUser2.Id, hooks[0].UserId)
+
+	t.Run("the team already exists", func(t *testing.T) {
+		_, err := th.App.CreateTeamFromTemplate(testTeamTemplateWithTeamName(th, team.Name), th.BasicUser2.Id, false)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.team_template.team_exists.app_error", err.Id)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		template := testTeamTemplateWithTeamName(th, team.Name)
+		template.Team.DisplayName = "Project Apollo"
+		template.Channels[0].Header = "Every day at 10"
+		template.Channels = append(template.Channels, &model.TeamTemplateChannel{Name: "retro", DisplayName: "Retro", Type: model.CHANNEL_OPEN})
+		template.DefaultChannels = []string{"standup", "retro"}
+		template.AdminEmails = append(template.AdminEmails, th.BasicUser2.Email)
+
+		for i := 0; i < 2; i++ {
+			updated, err := th.App.CreateTeamFromTemplate(template, th.BasicUser2.Id, true)
+			require.Nil(t, err)
+			assert.Equal(t, team.Id, updated.Id)
+			assert.Equal(t, "Project Apollo", updated.DisplayName)
+
+			standup := teamTemplateChannel(t, th, team.Id, "standup")
+			assert.Equal(t, "Every day at 10", standup.Header)
+
+			retro := teamTemplateChannel(t, th, team.Id, "retro")
+			require.NotNil(t, retro)
+
+			defaultChannels, err := th.App.GetTeamDefaultChannels(team.Id)
+			require.Nil(t, err)
+			assert.Equal(t, []string{standup.Id, retro.Id}, defaultChannels)
+
+			member, err := th.App.GetTeamMember(team.Id, th.BasicUser2.Id)
+			require.Nil(t, err)
+			assert.True(t, model.IsInRole(member.Roles, model.TEAM_ADMIN_ROLE_ID))
+
+			assert.Len(t, teamTemplateWebhooks(t, th, team.Id), 1, "the webhook shouldn't be created again")
+		}
+	})
+
+	t.Run("roll back a new team", func(t *testing.T) {
+		template := testTeamTemplate(th)
+		template.AdminEmails = append(template.AdminEmails, "nobody"+model.NewId()+"@example.com")
+
+		_, err := th.App.CreateTeamFromTemplate(template, th.BasicUser2.Id, false)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.team_template.admin_not_found.app_error", err.Id)
+
+		_, err = th.App.GetTeamByName(template.Team.Name)
+		assert.NotNil(t, err, "the team should have been removed")
+
+		// The same template works once it's fixed
+		template.AdminEmails = template.AdminEmails[:1]
+		_, err = th.App.CreateTeamFromTemplate(template, th.BasicUser2.Id, false)
+		assert.Nil(t, err)
+	})
+
+	t.Run("roll back an update", func(t *testing.T) {
+		user := th.CreateUser()
+
+		template := testTeamTemplateWithTeamName(th, team.Name)
+		template.Channels = append(template.Channels, &model.TeamTemplateChannel{Name: "incidents", DisplayName: "Incidents", Type: model.CHANNEL_OPEN})
+		template.DefaultChannels = []string{"incidents"}
+		template.AdminEmails = append(template.AdminEmails, user.Email)
+		template.IncomingWebhooks = append(template.IncomingWebhooks,
+			&model.TeamTemplateIncomingWebhook{ChannelName: "incidents", DisplayName: "Alerts"},
+			&model.TeamTemplateIncomingWebhook{ChannelName: "missing", DisplayName: "Nothing"},
+		)
+
+		defaultChannels, err := th.App.GetTeamDefaultChannels(team.Id)
+		require.Nil(t, err)
+
+		_, err = th.App.CreateTeamFromTemplate(template, th.BasicUser2.Id, true)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.team_template.channel_not_found.app_error", err.Id)
+
+		assert.Nil(t, teamTemplateChannel(t, th, team.Id, "incidents"), "the channel should have been removed")
+
+		restoredDefaultChannels, err := th.App.GetTeamDefaultChannels(team.Id)
+		require.Nil(t, err)
+		assert.Equal(t, defaultChannels, restoredDefaultChannels)
+
+		member, err := th.App.GetTeamMember(team.Id, user.Id)
+		if err == nil {
+			assert.NotZero(t, member.DeleteAt, "the admin should have been removed from the team")
+		}
+
+		for _, hook := range teamTemplateWebhooks(t, th, team.Id) {
+			assert.NotEqual(t, "Alerts", hook.DisplayName, "the webhook should have been removed")
+		}
+
+		_, err = th.App.GetTeamByName(team.Name)
+		assert.Nil(t, err, "the team should be left alone")
+	})
+
+	t.Run("webhooks need a creator", func(t *testing.T) {
+		template := testTeamTemplate(th)
+		template.AdminEmails = nil
+
+		_, err := th.App.CreateTeamFromTemplate(template, "", false)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.team_template.webhook_creator.app_error", err.Id)
+
+		template.AdminEmails = []string{th.BasicUser.Email}
+		created, err := th.App.CreateTeamFromTemplate(template, "", false)
+		require.Nil(t, err)
+
+		hooks := teamTemplateWebhooks(t, th, created.Id)
+		require.Len(t, hooks, 1)
+		assert.Equal(t, th.BasicUser.Id, hooks[0].UserId)
+	})
+
+	t.Run("invalid template", func(t *testing.T) {
+		_, err := th.App.CreateTeamFromTemplate(&model.TeamTemplate{}, th.BasicUser2.Id, false)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.team_template.no_team.app_error", err.Id)
+
+		template := testTeamTemplate(th)
+		template.Channels[0].Type = model.CHANNEL_DIRECT
+		_, err = th.App.CreateTeamFromTemplate(template, th.BasicUser2.Id, false)
+		require.NotNil(t, err)
+		assert.Equal(t, "model.team_template.is_valid.channel_type.app_error", err.Id)
+	})
+}
+
+func testTeamTemplateWithTeamName(th *TestHelper, name string) *model.TeamTemplate {
+	template := testTeamTemplate(th)
+	template.Team.Name = name
+	return template
+}
+
+func TestSavedTeamTemplates(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { cfg.ServiceSettings.EnableIncomingWebhooks = true })
+
+	template := testTeamTemplate(th)
+	template.Name = "Project " + model.NewId()
+	template.CreatorId = th.BasicUser.Id
+	template.Team.Name = ""
+	template.Team.DisplayName = ""
+
+	saved, err := th.App.SaveTeamTemplate(template)
+	require.Nil(t, err)
+	defer th.App.DeleteTeamTemplate(saved.Id)
+
+	templates, err := th.App.GetTeamTemplates(0, 1000)
+	require.Nil(t, err)
+	var ids []string
+	for _, template := range templates {
+		ids = append(ids, template.Id)
+	}
+	assert.Contains(t, ids, saved.Id)
+
+	team, err := th.App.CreateTeamFromSavedTemplate(saved.Id, &model.Team{Name: "apollo" + model.NewId(), DisplayName: "Apollo"}, th.BasicUser.Id, false)
+	require.Nil(t, err)
+	assert.Equal(t, "Apollo", team.DisplayName)
+	assert.NotNil(t, teamTemplateChannel(t, th, team.Id, "standup"))
+
+	saved.Description = "A team for each project"
+	saved.CreatorId = th.BasicUser2.Id
+	updated, err := th.App.UpdateTeamTemplate(saved)
+	require.Nil(t, err)
+	assert.Equal(t, "A team for each project", updated.Description)
+	assert.Equal(t, th.BasicUser.Id, updated.CreatorId)
+
+	require.Nil(t, th.App.DeleteTeamTemplate(saved.Id))
+	_, err = th.App.GetTeamTemplate(saved.Id)
+	assert.NotNil(t, err)
+}
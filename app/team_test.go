@@ -7,6 +7,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"github.com/mattermost/mattermost-server/model"
 )
 
@@ -183,6 +186,101 @@ func TestAddUserToTeamByToken(t *testing.T) {
 	})
 }
 
+func TestInvitationTokenJoinsChannels(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	privateChannel := th.createChannel(th.BasicTeam, model.CHANNEL_PRIVATE)
+
+	t.Run("new member", func(t *testing.T) {
+		invite := &model.Invite{Email: strings.ToLower(model.NewId()) + "success+test@example.com", Role: model.INVITE_ROLE_MEMBER, Channels: []string{privateChannel.Id}}
+		token, err := th.App.createInvitationToken(th.BasicTeam, invite)
+		require.Nil(t, err)
+		assert.Equal(t, TOKEN_TYPE_TEAM_INVITATION, token.Type)
+
+		user, err := th.App.CreateUserWithToken(&model.User{Username: "invited" + model.NewId(), Password: "passwd1"}, token.Token)
+		require.Nil(t, err)
+		assert.Equal(t, invite.Email, user.Email)
+
+		_, err = th.App.GetTeamMember(th.BasicTeam.Id, user.Id)
+		require.Nil(t, err)
+
+		_, err = th.App.GetChannelMember(privateChannel.Id, user.Id)
+		assert.Nil(t, err)
+	})
+
+	t.Run("existing user", func(t *testing.T) {
+		user := th.CreateUser()
+
+		token, err := th.App.createInvitationToken(th.BasicTeam, &model.Invite{Email: user.Email, Role: model.INVITE_ROLE_MEMBER, Channels: []string{privateChannel.Id}})
+		require.Nil(t, err)
+
+		_, err = th.App.AddUserToTeamByToken(user.Id, token.Token)
+		require.Nil(t, err)
+
+		_, err = th.App.GetChannelMember(privateChannel.Id, user.Id)
+		assert.Nil(t, err)
+	})
+
+	t.Run("guest", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.GuestAccountsSettings.Enable = true })
+
+		token, err := th.App.createInvitationToken(th.BasicTeam, &model.Invite{Email: strings.ToLower(model.NewId()) + "success+test@example.com", Role: model.INVITE_ROLE_GUEST, Channels: []string{privateChannel.Id}})
+		require.Nil(t, err)
+		assert.Equal(t, TOKEN_TYPE_GUEST_INVITATION, token.Type)
+
+		guest, err := th.App.CreateUserWithToken(&model.User{Username: "guest" + model.NewId(), Password: "passwd1"}, token.Token)
+		require.Nil(t, err)
+		assert.True(t, guest.IsGuest())
+
+		_, err = th.App.GetChannelMember(privateChannel.Id, guest.Id)
+		assert.Nil(t, err)
+
+		_, err = th.App.GetChannelMember(th.BasicChannel.Id, guest.Id)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestSendTeamInvites(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.GuestAccountsSettings.Enable = false })
+
+	otherChannel := th.CreateChannel(th.CreateTeam())
+	email := strings.ToLower(model.NewId()) + "success+test@example.com"
+
+	results, err := th.App.SendTeamInvites(th.BasicTeam.Id, []*model.Invite{
+		{Email: email, Channels: []string{th.BasicChannel.Id}, Message: "Welcome!"},
+		{Email: strings.ToUpper(email)},
+		{Email: th.BasicUser2.Email},
+		{Email: "not an email"},
+		{Email: strings.ToLower(model.NewId()) + "success+test@example.com", Channels: []string{otherChannel.Id}},
+		{Email: strings.ToLower(model.NewId()) + "success+test@example.com", Role: model.INVITE_ROLE_GUEST, Channels: []string{th.BasicChannel.Id}},
+	}, th.BasicUser.Id)
+	require.Nil(t, err)
+	require.Len(t, results, 6)
+
+	assert.Equal(t, email, results[0].Email)
+	assert.Nil(t, results[0].Error)
+
+	for i, id := range []string{
+		"",
+		"app.team.send_invites.duplicate.app_error",
+		"api.team.invite_members.already.app_error",
+		"model.invite.is_valid.email.app_error",
+		"app.team.send_invites.channel.app_error",
+		"api.team.invite_guests.disabled.app_error",
+	} {
+		if id != "" && assert.NotNil(t, results[i].Error, id) {
+			assert.Equal(t, id, results[i].Error.Id)
+		}
+	}
+
+	_, err = th.App.SendTeamInvites(th.BasicTeam.Id, []*model.Invite{}, th.BasicUser.Id)
+	assert.NotNil(t, err)
+}
+
 func TestAddUserToTeamByTeamId(t *testing.T) {
 	th := Setup().InitBasic()
 	defer th.TearDown()
@@ -559,3 +657,163 @@ func TestJoinUserToTeam(t *testing.T) {
 		}
 	})
 }
+
+func TestTeamDefaultChannels(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	channel1 := th.CreateChannel(th.BasicTeam)
+	channel2 := th.CreateChannel(th.BasicTeam)
+	channel3 := th.CreateChannel(th.BasicTeam)
+
+	channelIds, err := th.App.UpdateTeamDefaultChannels(th.BasicTeam.Id, []string{channel1.Id, channel2.Id, channel3.Id, channel1.Id})
+	require.Nil(t, err)
+	assert.Len(t, channelIds, 3)
+
+	channelIds, err = th.App.GetTeamDefaultChannels(th.BasicTeam.Id)
+	require.Nil(t, err)
+	assert.Len(t, channelIds, 3)
+
+	assertJoinedDefaultChannels := func(t *testing.T, user *model.User) {
+		for _, channel := range []*model.Channel{channel1, channel2, channel3} {
+			_, err := th.App.GetChannelMember(channel.Id, user.Id)
+			assert.Nil(t, err, "should have joined "+channel.Name)
+		}
+
+		townSquare, err := th.App.GetChannelByName(model.DEFAULT_CHANNEL, th.BasicTeam.Id)
+		require.Nil(t, err)
+		_, err = th.App.GetChannelMember(townSquare.Id, user.Id)
+		assert.Nil(t, err, "should still have joined town square")
+	}
+
+	t.Run("invited by email", func(t *testing.T) {
+		user := th.CreateUser()
+
+		token := model.NewToken(
+			TOKEN_TYPE_TEAM_INVITATION,
+			model.MapToJson(map[string]string{"teamId": th.BasicTeam.Id}),
+		)
+		<-th.App.Srv.Store.Token().Save(token)
+
+		_, err := th.App.AddUserToTeamByToken(user.Id, token.Token)
+		require.Nil(t, err)
+
+		assertJoinedDefaultChannels(t, user)
+	})
+
+	t.Run("provisioned by SSO", func(t *testing.T) {
+		user := th.CreateUser()
+
+		require.Nil(t, th.App.AddUserToTeamByTeamId(th.BasicTeam.Id, user))
+
+		assertJoinedDefaultChannels(t, user)
+	})
+
+	t.Run("removing a default channel keeps its members", func(t *testing.T) {
+		user := th.CreateUser()
+		th.LinkUserToTeam(user, th.BasicTeam)
+
+		_, err := th.App.UpdateTeamDefaultChannels(th.BasicTeam.Id, []string{channel2.Id})
+		require.Nil(t, err)
+
+		_, err = th.App.GetChannelMember(channel1.Id, user.Id)
+		assert.Nil(t, err)
+
+		newUser := th.CreateUser()
+		th.LinkUserToTeam(newUser, th.BasicTeam)
+
+		_, err = th.App.GetChannelMember(channel1.Id, newUser.Id)
+		assert.NotNil(t, err)
+		_, err = th.App.GetChannelMember(channel2.Id, newUser.Id)
+		assert.Nil(t, err)
+	})
+
+	t.Run("invalid channels", func(t *testing.T) {
+		private := th.createChannel(th.BasicTeam, model.CHANNEL_PRIVATE)
+		_, err := th.App.UpdateTeamDefaultChannels(th.BasicTeam.Id, []string{private.Id})
+		require.NotNil(t, err)
+		assert.Equal(t, "app.team.update_default_channels.channel.app_error", err.Id)
+
+		otherTeam := th.CreateTeam()
+		otherChannel := th.CreateChannel(otherTeam)
+		_, err = th.App.UpdateTeamDefaultChannels(th.BasicTeam.Id, []string{otherChannel.Id})
+		require.NotNil(t, err)
+
+		_, err = th.App.UpdateTeamDefaultChannels(th.BasicTeam.Id, []string{model.NewId()})
+		require.NotNil(t, err)
+
+		channelIds, err := th.App.GetTeamDefaultChannels(th.BasicTeam.Id)
+		require.Nil(t, err)
+		assert.Equal(t, []string{channel2.Id}, channelIds)
+	})
+}
+
+func TestWelcomeMessage(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.AnnouncementSettings.EnableWelcomeMessage = true
+		*cfg.AnnouncementSettings.WelcomeMessage = "Welcome to {{.TeamName}}, {{.UserFirstName}}!"
+	})
+
+	getWelcomeMessages := func(user *model.User) []string {
+		bot, err := th.App.EnsureSystemBot()
+		require.Nil(t, err)
+
+		result := <-th.App.Srv.Store.Channel().GetByName("", model.GetDMNameFromIds(bot.Id, user.Id), true)
+		if result.Err != nil {
+			return nil
+		}
+
+		posts, err := th.App.GetPosts(result.Data.(*model.Channel).Id, 0, 10)
+		require.Nil(t, err)
+
+		messages := []string{}
+		for _, id := range posts.Order {
+			assert.Equal(t, bot.Id, posts.Posts[id].UserId)
+			messages = append(messages, posts.Posts[id].Message)
+		}
+		return messages
+	}
+
+	t.Run("first team", func(t *testing.T) {
+		user := th.CreateUser()
+		user.FirstName = "Jo"
+		th.LinkUserToTeam(user, th.BasicTeam)
+
+		assert.Equal(t, []string{"Welcome to " + th.BasicTeam.DisplayName + ", Jo!"}, getWelcomeMessages(user))
+
+		// joining another team doesn't send another welcome
+		th.LinkUserToTeam(user, th.CreateTeam())
+		assert.Len(t, getWelcomeMessages(user), 1)
+	})
+
+	t.Run("username without a first name", func(t *testing.T) {
+		user := th.CreateUser()
+		th.LinkUserToTeam(user, th.BasicTeam)
+
+		assert.Equal(t, []string{"Welcome to " + th.BasicTeam.DisplayName + ", " + user.Username + "!"}, getWelcomeMessages(user))
+	})
+
+	t.Run("team override", func(t *testing.T) {
+		message := "Read the handbook in ~handbook, {{.UserFirstName}}."
+		team, err := th.App.PatchTeam(th.CreateTeam().Id, &model.TeamPatch{WelcomeMessage: &message})
+		require.Nil(t, err)
+
+		user := th.CreateUser()
+		th.LinkUserToTeam(user, team)
+
+		assert.Equal(t, []string{"Read the handbook in ~handbook, " + user.Username + "."}, getWelcomeMessages(user))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.AnnouncementSettings.EnableWelcomeMessage = false })
+		defer th.App.UpdateConfig(func(cfg *model.Config) { *cfg.AnnouncementSettings.EnableWelcomeMessage = true })
+
+		user := th.CreateUser()
+		th.LinkUserToTeam(user, th.BasicTeam)
+
+		assert.Empty(t, getWelcomeMessages(user))
+	})
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// CreateTermsOfService saves a new version of the terms of service, which every user has to accept again.
+func (a *App) CreateTermsOfService(text string, userId string) (*model.TermsOfService, *model.AppError) {
+	result := <-a.Srv.Store.TermsOfService().Save(&model.TermsOfService{
+		Text:   text,
+		UserId: userId,
+	})
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	// Cached sessions were flagged against the previous version
+	a.ClearSessionCacheForAllUsers()
+
+	return result.Data.(*model.TermsOfService), nil
+}
+
+func (a *App) GetLatestTermsOfService() (*model.TermsOfService, *model.AppError) {
+	result := <-a.Srv.Store.TermsOfService().GetLatest()
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.TermsOfService), nil
+}
+
+func (a *App) GetTermsOfService(id string) (*model.TermsOfService, *model.AppError) {
+	result := <-a.Srv.Store.TermsOfService().Get(id)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.TermsOfService), nil
+}
+
+func (a *App) GetUserTermsOfService(userId string) (*model.UserTermsOfService, *model.AppError) {
+	result := <-a.Srv.Store.TermsOfService().GetUserTermsOfService(userId)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.UserTermsOfService), nil
+}
+
+// AcceptTermsOfService records that the user accepted the given version of the terms of service. Only
+// accepting the latest version lifts the flag on the user's sessions.
+func (a *App) AcceptTermsOfService(userId string, termsOfServiceId string) *model.AppError {
+	if _, err := a.GetTermsOfService(termsOfServiceId); err != nil {
+		if err.StatusCode == http.StatusNotFound {
+			err.StatusCode = http.StatusBadRequest
+		}
+		return err
+	}
+
+	if result := <-a.Srv.Store.TermsOfService().SaveUserTermsOfService(&model.UserTermsOfService{
+		UserId:           userId,
+		TermsOfServiceId: termsOfServiceId,
+	}); result.Err != nil {
+		return result.Err
+	}
+
+	a.ClearSessionCacheForUser(userId)
+
+	return nil
+}
+
+// UserRequiresTermsOfService returns whether the user has yet to accept the latest custom terms of service.
+func (a *App) UserRequiresTermsOfService(userId string) bool {
+	if !*a.Config().SupportSettings.CustomTermsOfServiceEnabled {
+		return false
+	}
+
+	latest, err := a.GetLatestTermsOfService()
+	if err != nil {
+		if err.StatusCode != http.StatusNotFound {
+			mlog.Error("Unable to get the latest terms of service", mlog.String("error", err.Error()))
+		}
+		return false
+	}
+
+	accepted, err := a.GetUserTermsOfService(userId)
+	if err != nil {
+		return true
+	}
+
+	return accepted.TermsOfServiceId != latest.Id
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestTermsOfServiceSessionFlag(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.SupportSettings.CustomTermsOfServiceEnabled = true })
+
+	session, err := th.App.CreateSession(&model.Session{UserId: th.BasicUser.Id})
+	require.Nil(t, err)
+
+	first, err := th.App.CreateTermsOfService("First version", th.BasicUser2.Id)
+	require.Nil(t, err)
+
+	session, err = th.App.GetSession(session.Token)
+	require.Nil(t, err)
+	assert.True(t, session.TermsOfServiceRequired)
+
+	err = th.App.AcceptTermsOfService(th.BasicUser.Id, model.NewId())
+	require.NotNil(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.StatusCode)
+
+	require.Nil(t, th.App.AcceptTermsOfService(th.BasicUser.Id, first.Id))
+
+	session, err = th.App.GetSession(session.Token)
+	require.Nil(t, err)
+	assert.False(t, session.TermsOfServiceRequired)
+
+	newSession, err := th.App.CreateSession(&model.Session{UserId: th.BasicUser.Id})
+	require.Nil(t, err)
+	assert.False(t, newSession.TermsOfServiceRequired)
+
+	// A new version has to be accepted again
+	time.Sleep(2 * time.Millisecond)
+	second, err := th.App.CreateTermsOfService("Second version", th.BasicUser2.Id)
+	require.Nil(t, err)
+
+	session, err = th.App.GetSession(session.Token)
+	require.Nil(t, err)
+	assert.True(t, session.TermsOfServiceRequired)
+
+	require.Nil(t, th.App.AcceptTermsOfService(th.BasicUser.Id, first.Id))
+	assert.True(t, th.App.UserRequiresTermsOfService(th.BasicUser.Id))
+
+	require.Nil(t, th.App.AcceptTermsOfService(th.BasicUser.Id, second.Id))
+	assert.False(t, th.App.UserRequiresTermsOfService(th.BasicUser.Id))
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.SupportSettings.CustomTermsOfServiceEnabled = false })
+	assert.False(t, th.App.UserRequiresTermsOfService(th.BasicUser2.Id))
+}
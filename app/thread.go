@@ -0,0 +1,418 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func (a *App) GetThreadsForUser(userId string, teamId string, page int, perPage int) (*model.Threads, *model.AppError) {
+	result := <-a.Srv.Store.Thread().GetThreadsForUser(userId, teamId, page*perPage, perPage)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	threads := result.Data.(*model.Threads)
+
+	if len(threads.Threads) == 0 {
+		return threads, nil
+	}
+
+	postIds := make([]string, len(threads.Threads))
+	for i, thread := range threads.Threads {
+		postIds[i] = thread.PostId
+	}
+
+	presult := <-a.Srv.Store.Post().GetPostsByIds(postIds)
+	if presult.Err != nil {
+		return nil, presult.Err
+	}
+
+	posts := make(map[string]*model.Post)
+	for _, post := range presult.Data.([]*model.Post) {
+		posts[post.Id] = a.PostWithProxyAddedToImageURLs(post)
+	}
+
+	for _, thread := range threads.Threads {
+		thread.Post = posts[thread.PostId]
+	}
+
+	return threads, nil
+}
+
+func (a *App) GetThreadForUser(userId string, teamId string, threadId string) (*model.ThreadResponse, *model.AppError) {
+	if _, err := a.getThreadInTeam(teamId, threadId); err != nil {
+		return nil, err
+	}
+
+	result := <-a.Srv.Store.Thread().GetThreadForUser(userId, threadId)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	thread := result.Data.(*model.ThreadResponse)
+
+	presult := <-a.Srv.Store.Post().GetSingle(threadId)
+	if presult.Err != nil {
+		return nil, presult.Err
+	}
+	thread.Post = a.PostWithProxyAddedToImageURLs(presult.Data.(*model.Post))
+
+	return thread, nil
+}
+
+// UpdateThreadReadForUser marks the thread as having been read by the user up to the given time.
+func (a *App) UpdateThreadReadForUser(userId string, teamId string, threadId string, timestamp int64) (*model.ThreadResponse, *model.AppError) {
+	if _, err := a.getThreadInTeam(teamId, threadId); err != nil {
+		return nil, err
+	}
+
+	membership, err := a.getOrCreateThreadMembership(userId, threadId, false)
+	if err != nil {
+		return nil, err
+	}
+
+	membership.LastViewed = timestamp
+	membership.LastUpdated = model.GetMillis()
+	membership.UnreadMentions = 0
+
+	if result := <-a.Srv.Store.Thread().UpdateMembership(membership); result.Err != nil {
+		return nil, result.Err
+	}
+
+	return a.publishThreadUpdatedForUser(userId, teamId, threadId)
+}
+
+// UpdateThreadFollowForUser starts or stops the user from following the thread.
+func (a *App) UpdateThreadFollowForUser(userId string, teamId string, threadId string, following bool) (*model.ThreadResponse, *model.AppError) {
+	if _, err := a.getThreadInTeam(teamId, threadId); err != nil {
+		return nil, err
+	}
+
+	membership, err := a.getOrCreateThreadMembership(userId, threadId, following)
+	if err != nil {
+		return nil, err
+	}
+
+	if membership.Following != following {
+		membership.Following = following
+		membership.LastUpdated = model.GetMillis()
+
+		if result := <-a.Srv.Store.Thread().UpdateMembership(membership); result.Err != nil {
+			return nil, result.Err
+		}
+	}
+
+	return a.publishThreadUpdatedForUser(userId, teamId, threadId)
+}
+
+// getThreadInTeam returns the thread with the given root post id, making sure that it belongs to
+// either the given team or to a DM or group message.
+func (a *App) getThreadInTeam(teamId string, threadId string) (*model.Thread, *model.AppError) {
+	result := <-a.Srv.Store.Thread().Get(threadId)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	thread := result.Data.(*model.Thread)
+
+	channel, err := a.GetChannel(thread.ChannelId)
+	if err != nil {
+		return nil, err
+	}
+
+	if channel.TeamId != "" && channel.TeamId != teamId {
+		return nil, model.NewAppError("getThreadInTeam", "app.thread.get.wrong_team.app_error", nil, "thread_id="+threadId+", team_id="+teamId, http.StatusNotFound)
+	}
+
+	return thread, nil
+}
+
+func (a *App) getOrCreateThreadMembership(userId string, threadId string, following bool) (*model.ThreadMembership, *model.AppError) {
+	result := <-a.Srv.Store.Thread().GetMembershipForUser(userId, threadId)
+	if result.Err == nil {
+		return result.Data.(*model.ThreadMembership), nil
+	} else if result.Err.StatusCode != http.StatusNotFound {
+		return nil, result.Err
+	}
+
+	membership := &model.ThreadMembership{
+		PostId:      threadId,
+		UserId:      userId,
+		Following:   following,
+		LastUpdated: model.GetMillis(),
+	}
+
+	if result := <-a.Srv.Store.Thread().SaveMembership(membership); result.Err != nil {
+		return nil, result.Err
+	}
+
+	return membership, nil
+}
+
+func (a *App) publishThreadUpdatedForUser(userId string, teamId string, threadId string) (*model.ThreadResponse, *model.AppError) {
+	thread, err := a.GetThreadForUser(userId, teamId, threadId)
+	if err != nil {
+		return nil, err
+	}
+
+	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_THREAD_UPDATED, teamId, "", userId, nil)
+	message.Add("thread", thread.ToJson())
+	a.Publish(message)
+
+	return thread, nil
+}
+
+// updateThreadForReply updates the thread that a newly created reply belongs to. Everyone who has
+// posted in the thread follows it unless they've chosen to stop following it.
+func (a *App) updateThreadForReply(post *model.Post, channel *model.Channel, parentPostList *model.PostList) *model.AppError {
+	posts := threadPostsFromList(parentPostList)
+	posts = append(posts, post)
+
+	thread := &model.Thread{
+		PostId:       post.RootId,
+		ChannelId:    post.ChannelId,
+		ReplyCount:   int64(len(posts) - 1),
+		LastReplyAt:  post.CreateAt,
+		Participants: threadParticipants(posts),
+	}
+
+	if result := <-a.Srv.Store.Thread().Get(post.RootId); result.Err != nil {
+		if result.Err.StatusCode != http.StatusNotFound {
+			return result.Err
+		}
+
+		if result := <-a.Srv.Store.Thread().Save(thread); result.Err != nil {
+			return result.Err
+		}
+	} else if result := <-a.Srv.Store.Thread().Update(thread); result.Err != nil {
+		return result.Err
+	}
+
+	result := <-a.Srv.Store.Thread().GetMembershipsForThread(post.RootId)
+	if result.Err != nil {
+		return result.Err
+	}
+	memberships := result.Data.([]*model.ThreadMembership)
+
+	existing := make(map[string]*model.ThreadMembership, len(memberships))
+	for _, membership := range memberships {
+		existing[membership.UserId] = membership
+	}
+
+	now := model.GetMillis()
+
+	// Anyone who posted before threads were tracked is assumed to have read up to their last post
+	lastPostAt := make(map[string]int64)
+	for _, threadPost := range posts {
+		lastPostAt[threadPost.UserId] = threadPost.CreateAt
+	}
+
+	for _, participant := range thread.Participants {
+		if _, ok := existing[participant]; ok {
+			continue
+		}
+
+		membership := &model.ThreadMembership{
+			PostId:      post.RootId,
+			UserId:      participant,
+			Following:   true,
+			LastViewed:  lastPostAt[participant],
+			LastUpdated: now,
+		}
+
+		if result := <-a.Srv.Store.Thread().SaveMembership(membership); result.Err != nil {
+			return result.Err
+		}
+
+		memberships = append(memberships, membership)
+		existing[participant] = membership
+	}
+
+	// Replying to a thread follows it again and marks it as read
+	if membership := existing[post.UserId]; membership.LastViewed != post.CreateAt {
+		membership.Following = true
+		membership.LastViewed = post.CreateAt
+		membership.LastUpdated = now
+		membership.UnreadMentions = 0
+
+		if result := <-a.Srv.Store.Thread().UpdateMembership(membership); result.Err != nil {
+			return result.Err
+		}
+	}
+
+	a.publishThreadUpdated(thread, channel, posts, memberships)
+
+	return nil
+}
+
+// updateThreadForDeletedPost removes the thread for a deleted root post, or recounts the replies in
+// the thread that a deleted reply belonged to.
+func (a *App) updateThreadForDeletedPost(post *model.Post) *model.AppError {
+	if post.RootId == "" {
+		if result := <-a.Srv.Store.Thread().Delete(post.Id); result.Err != nil {
+			return result.Err
+		}
+
+		return nil
+	}
+
+	result := <-a.Srv.Store.Thread().Get(post.RootId)
+	if result.Err != nil {
+		if result.Err.StatusCode == http.StatusNotFound {
+			return nil
+		}
+
+		return result.Err
+	}
+	thread := result.Data.(*model.Thread)
+
+	presult := <-a.Srv.Store.Post().Get(post.RootId)
+	if presult.Err != nil {
+		return presult.Err
+	}
+	posts := threadPostsFromList(presult.Data.(*model.PostList))
+
+	thread.ReplyCount = int64(len(posts) - 1)
+	thread.LastReplyAt = 0
+	if len(posts) > 1 {
+		thread.LastReplyAt = posts[len(posts)-1].CreateAt
+	}
+
+	if result := <-a.Srv.Store.Thread().Update(thread); result.Err != nil {
+		return result.Err
+	}
+
+	mresult := <-a.Srv.Store.Thread().GetMembershipsForThread(post.RootId)
+	if mresult.Err != nil {
+		return mresult.Err
+	}
+
+	channel, err := a.GetChannel(thread.ChannelId)
+	if err != nil {
+		return err
+	}
+
+	a.publishThreadUpdated(thread, channel, posts, mresult.Data.([]*model.ThreadMembership))
+
+	return nil
+}
+
+// publishThreadUpdated notifies each user following the thread of its new state, as long as they're
+// still a member of its channel. posts must contain the root post followed by its replies in the order
+// that they were made.
+func (a *App) publishThreadUpdated(thread *model.Thread, channel *model.Channel, posts []*model.Post, memberships []*model.ThreadMembership) {
+	var userIds []string
+	for _, membership := range memberships {
+		if membership.Following {
+			userIds = append(userIds, membership.UserId)
+		}
+	}
+
+	if len(userIds) == 0 {
+		return
+	}
+
+	result := <-a.Srv.Store.Channel().GetMembersByIds(channel.Id, userIds)
+	if result.Err != nil {
+		mlog.Warn("Failed to get the channel members to send a thread update to", mlog.String("thread_id", thread.PostId), mlog.String("error", result.Err.Error()))
+		return
+	}
+
+	channelMembers := make(map[string]bool, len(userIds))
+	for _, member := range *result.Data.(*model.ChannelMembers) {
+		channelMembers[member.UserId] = true
+	}
+
+	root := a.PostWithProxyAddedToImageURLs(posts[0])
+
+	for _, membership := range memberships {
+		if !membership.Following || !channelMembers[membership.UserId] {
+			continue
+		}
+
+		response := &model.ThreadResponse{
+			PostId:         thread.PostId,
+			ChannelId:      thread.ChannelId,
+			ReplyCount:     thread.ReplyCount,
+			LastReplyAt:    thread.LastReplyAt,
+			LastViewedAt:   membership.LastViewed,
+			Participants:   thread.Participants,
+			UnreadReplies:  countUnreadReplies(posts, membership.LastViewed),
+			UnreadMentions: membership.UnreadMentions,
+			Post:           root,
+		}
+
+		message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_THREAD_UPDATED, channel.TeamId, "", membership.UserId, nil)
+		message.Add("thread", response.ToJson())
+		a.Publish(message)
+	}
+}
+
+// countsMentionsInThread returns true if mentions of the user in a reply to the thread should count
+// toward the thread's unread mentions instead of the channel's.
+func (a *App) countsMentionsInThread(userId string, threadId string) bool {
+	if !a.collapsedThreadsEnabled(userId) {
+		return false
+	}
+
+	if result := <-a.Srv.Store.Thread().GetMembershipForUser(userId, threadId); result.Err != nil {
+		return false
+	} else {
+		return result.Data.(*model.ThreadMembership).Following
+	}
+}
+
+// collapsedThreadsEnabled returns true if the user has chosen to see replies in threads instead of in channels.
+func (a *App) collapsedThreadsEnabled(userId string) bool {
+	result := <-a.Srv.Store.Preference().Get(userId, model.PREFERENCE_CATEGORY_DISPLAY_SETTINGS, model.PREFERENCE_NAME_COLLAPSED_THREADS)
+	return result.Err == nil && result.Data.(model.Preference).Value == model.COLLAPSED_THREADS_ON
+}
+
+// threadPostsFromList returns the posts in the list with the root post first and then its replies
+// in the order that they were made.
+func threadPostsFromList(list *model.PostList) []*model.Post {
+	posts := make([]*model.Post, 0, len(list.Posts))
+	for _, post := range list.Posts {
+		posts = append(posts, post)
+	}
+
+	sort.Slice(posts, func(i, j int) bool {
+		if posts[i].RootId == "" {
+			return posts[j].RootId != ""
+		} else if posts[j].RootId == "" {
+			return false
+		}
+
+		return posts[i].CreateAt < posts[j].CreateAt
+	})
+
+	return posts
+}
+
+func threadParticipants(posts []*model.Post) model.StringArray {
+	participants := model.StringArray{}
+	seen := make(map[string]bool)
+
+	for _, post := range posts {
+		if !seen[post.UserId] {
+			participants = append(participants, post.UserId)
+			seen[post.UserId] = true
+		}
+	}
+
+	return participants
+}
+
+func countUnreadReplies(posts []*model.Post, lastViewed int64) int64 {
+	var count int64
+	for _, post := range posts[1:] {
+		if post.CreateAt > lastViewed {
+			count++
+		}
+	}
+
+	return count
+}
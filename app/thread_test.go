@@ -0,0 +1,193 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func (me *TestHelper) createReply(user *model.User, root *model.Post, message string) *model.Post {
+	// Make sure that each reply is created at a different time than the last
+	time.Sleep(time.Millisecond)
+
+	reply, err := me.App.CreatePost(&model.Post{
+		UserId:    user.Id,
+		ChannelId: root.ChannelId,
+		RootId:    root.Id,
+		ParentId:  root.Id,
+		Message:   message,
+	}, me.BasicChannel, false)
+	if err != nil {
+		panic(err)
+	}
+
+	return reply
+}
+
+func TestUpdateThreadForReply(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.AddUserToChannel(th.BasicUser2, th.BasicChannel)
+
+	root := th.BasicPost
+	reply1 := th.createReply(th.BasicUser2, root, "first reply")
+
+	thread, err := th.App.GetThreadForUser(th.BasicUser.Id, th.BasicTeam.Id, root.Id)
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), thread.ReplyCount)
+	assert.Equal(t, reply1.CreateAt, thread.LastReplyAt)
+	assert.Equal(t, model.StringArray{th.BasicUser.Id, th.BasicUser2.Id}, thread.Participants)
+	assert.Equal(t, int64(1), thread.UnreadReplies)
+	assert.Equal(t, root.Id, thread.Post.Id)
+
+	thread, err = th.App.GetThreadForUser(th.BasicUser2.Id, th.BasicTeam.Id, root.Id)
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), thread.UnreadReplies)
+
+	th.createReply(th.BasicUser2, root, "second reply")
+
+	thread, err = th.App.GetThreadForUser(th.BasicUser.Id, th.BasicTeam.Id, root.Id)
+	require.Nil(t, err)
+	assert.Equal(t, int64(2), thread.ReplyCount)
+	assert.Equal(t, int64(2), thread.UnreadReplies)
+
+	thread, err = th.App.UpdateThreadReadForUser(th.BasicUser.Id, th.BasicTeam.Id, root.Id, reply1.CreateAt)
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), thread.UnreadReplies)
+
+	threads, err := th.App.GetThreadsForUser(th.BasicUser.Id, th.BasicTeam.Id, 0, 60)
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), threads.Total)
+	assert.Equal(t, int64(1), threads.TotalUnreadThreads)
+	require.Len(t, threads.Threads, 1)
+	assert.Equal(t, root.Id, threads.Threads[0].Post.Id)
+
+	_, err = th.App.UpdateThreadFollowForUser(th.BasicUser.Id, th.BasicTeam.Id, root.Id, false)
+	require.Nil(t, err)
+
+	th.createReply(th.BasicUser2, root, "third reply")
+
+	threads, err = th.App.GetThreadsForUser(th.BasicUser.Id, th.BasicTeam.Id, 0, 60)
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), threads.Total)
+
+	// Replying again follows the thread again
+	th.createReply(th.BasicUser, root, "fourth reply")
+
+	thread, err = th.App.GetThreadForUser(th.BasicUser.Id, th.BasicTeam.Id, root.Id)
+	require.Nil(t, err)
+	assert.Equal(t, int64(4), thread.ReplyCount)
+	assert.Equal(t, int64(0), thread.UnreadReplies)
+
+	_, err = th.App.GetThreadForUser(th.BasicUser.Id, model.NewId(), root.Id)
+	assert.NotNil(t, err)
+}
+
+func TestThreadMentions(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.AddUserToChannel(th.BasicUser2, th.BasicChannel)
+
+	root := th.BasicPost
+	th.createReply(th.BasicUser2, root, "first reply")
+
+	th.createReply(th.BasicUser2, root, "@"+th.BasicUser.Username+" without collapsed threads")
+
+	thread, err := th.App.GetThreadForUser(th.BasicUser.Id, th.BasicTeam.Id, root.Id)
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), thread.UnreadMentions)
+
+	err = th.App.UpdatePreferences(th.BasicUser.Id, model.Preferences{{
+		UserId:   th.BasicUser.Id,
+		Category: model.PREFERENCE_CATEGORY_DISPLAY_SETTINGS,
+		Name:     model.PREFERENCE_NAME_COLLAPSED_THREADS,
+		Value:    model.COLLAPSED_THREADS_ON,
+	}})
+	require.Nil(t, err)
+
+	member, err := th.App.GetChannelMember(th.BasicChannel.Id, th.BasicUser.Id)
+	require.Nil(t, err)
+	channelMentions := member.MentionCount
+
+	th.createReply(th.BasicUser2, root, "@"+th.BasicUser.Username+" with collapsed threads")
+
+	thread, err = th.App.GetThreadForUser(th.BasicUser.Id, th.BasicTeam.Id, root.Id)
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), thread.UnreadMentions)
+
+	member, err = th.App.GetChannelMember(th.BasicChannel.Id, th.BasicUser.Id)
+	require.Nil(t, err)
+	assert.Equal(t, channelMentions, member.MentionCount)
+
+	thread, err = th.App.UpdateThreadReadForUser(th.BasicUser.Id, th.BasicTeam.Id, root.Id, model.GetMillis())
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), thread.UnreadMentions)
+}
+
+func TestThreadsAfterLeavingChannel(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.AddUserToChannel(th.BasicUser2, th.BasicChannel)
+
+	root := th.BasicPost
+	th.createReply(th.BasicUser2, root, "first reply")
+
+	threads, err := th.App.GetThreadsForUser(th.BasicUser2.Id, th.BasicTeam.Id, 0, 60)
+	require.Nil(t, err)
+	require.Equal(t, int64(1), threads.Total)
+
+	require.Nil(t, th.App.RemoveUserFromChannel(th.BasicUser2.Id, th.BasicUser.Id, th.BasicChannel))
+
+	result := <-th.App.Srv.Store.Thread().GetMembershipForUser(th.BasicUser2.Id, root.Id)
+	require.NotNil(t, result.Err, "leaving the channel should remove the user from its threads")
+
+	threads, err = th.App.GetThreadsForUser(th.BasicUser2.Id, th.BasicTeam.Id, 0, 60)
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), threads.Total)
+	assert.Empty(t, threads.Threads)
+
+	_, err = th.App.GetThreadForUser(th.BasicUser2.Id, th.BasicTeam.Id, root.Id)
+	assert.NotNil(t, err)
+
+	t.Run("thread updates aren't sent to a former member", func(t *testing.T) {
+		member := newTestHubWebConn(th.App, th.BasicUser.Id)
+		former := newTestHubWebConn(th.App, th.BasicUser2.Id)
+		th.App.HubRegister(member)
+		th.App.HubRegister(former)
+		defer th.App.HubUnregister(member)
+		defer th.App.HubUnregister(former)
+
+		// A membership left behind before memberships were removed on leaving
+		result := <-th.App.Srv.Store.Thread().SaveMembership(&model.ThreadMembership{PostId: root.Id, UserId: th.BasicUser2.Id, Following: true})
+		require.Nil(t, result.Err)
+
+		th.createReply(th.BasicUser, root, "second reply")
+
+		receivedThreadUpdated := func(wc *WebConn, timeout time.Duration) bool {
+			deadline := time.After(timeout)
+			for {
+				select {
+				case msg := <-wc.Send:
+					if evt, ok := msg.(*model.WebSocketEvent); ok && evt.Event == model.WEBSOCKET_EVENT_THREAD_UPDATED {
+						return true
+					}
+				case <-deadline:
+					return false
+				}
+			}
+		}
+
+		require.True(t, receivedThreadUpdated(member, 5*time.Second), "the member should be sent the thread update")
+		assert.False(t, receivedThreadUpdated(former, 200*time.Millisecond), "the former member shouldn't be sent the thread update")
+	})
+}
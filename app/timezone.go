@@ -4,6 +4,9 @@
 package app
 
 import (
+	"time"
+
+	"github.com/mattermost/mattermost-server/mlog"
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/utils"
 )
@@ -26,3 +29,27 @@ func (a *App) LoadTimezones() {
 
 	a.timezones.Store(timezoneCfg)
 }
+
+// GetUserTimezoneLocation returns the location that times are shown in to the user, which is their preferred
+// timezone or the server's default timezone if they haven't chosen one or it isn't recognized.
+func (a *App) GetUserTimezoneLocation(user *model.User) *time.Location {
+	if timezone := user.GetPreferredTimezone(); timezone != "" {
+		if location, err := time.LoadLocation(timezone); err == nil {
+			return location
+		}
+	}
+
+	return a.GetDefaultTimezoneLocation()
+}
+
+// GetDefaultTimezoneLocation returns the location of the timezone set in the server's config, or UTC if it's
+// not recognized.
+func (a *App) GetDefaultTimezoneLocation() *time.Location {
+	location, err := time.LoadLocation(*a.Config().ServiceSettings.DefaultTimezone)
+	if err != nil {
+		mlog.Warn("Unable to load the default timezone", mlog.String("timezone", *a.Config().ServiceSettings.DefaultTimezone), mlog.Err(err))
+		return time.UTC
+	}
+
+	return location
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const USER_TYPING_CACHE_SIZE = 10000
+
+// PublishUserTyping tells the other users in the channel that the user is typing. If parentId is set, the user is
+// replying in a thread, and only the users who are following the thread or who have it open are told.
+//
+// Updates for the same channel or thread that come sooner than half of TimeBetweenUserTypingUpdatesMilliseconds after
+// the last one are dropped, which leaves room for clients whose updates arrive a little early.
+func (a *App) PublishUserTyping(userId string, channelId string, parentId string) *model.AppError {
+	rootId := ""
+	if parentId != "" {
+		result := <-a.Srv.Store.Post().GetSingle(parentId)
+		if result.Err != nil {
+			return result.Err
+		}
+		parent := result.Data.(*model.Post)
+
+		if parent.ChannelId != channelId {
+			return model.NewAppError("PublishUserTyping", "app.user_typing.parent_id.app_error", nil, "channel_id="+channelId+", parent_id="+parentId, http.StatusBadRequest)
+		}
+
+		rootId = parent.Id
+		if parent.RootId != "" {
+			rootId = parent.RootId
+		}
+	}
+
+	key := userId + ":" + channelId + ":" + rootId
+	now := model.GetMillis()
+	if lastTypingAt, ok := a.userTypingCache.Get(key); ok && now-lastTypingAt.(int64) < *a.Config().ServiceSettings.TimeBetweenUserTypingUpdatesMilliseconds/2 {
+		return nil
+	}
+	a.userTypingCache.Add(key, now)
+
+	event := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_TYPING, "", channelId, "", map[string]bool{userId: true})
+	event.Add("parent_id", parentId)
+	event.Add("user_id", userId)
+
+	if rootId != "" {
+		result := <-a.Srv.Store.Thread().GetMembershipsForThread(rootId)
+		if result.Err != nil {
+			return result.Err
+		}
+
+		followers := make(map[string]bool)
+		for _, membership := range result.Data.([]*model.ThreadMembership) {
+			if membership.Following {
+				followers[membership.UserId] = true
+			}
+		}
+
+		event.Add("root_id", rootId)
+		event.Broadcast.ThreadId = rootId
+		event.Broadcast.ThreadFollowers = followers
+	}
+
+	a.Publish(event)
+
+	return nil
+}
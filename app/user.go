@@ -21,20 +21,25 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/disintegration/imaging"
 	"github.com/golang/freetype"
+	"github.com/golang/freetype/truetype"
 	"github.com/mattermost/mattermost-server/einterfaces"
 	"github.com/mattermost/mattermost-server/mlog"
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/store"
 	"github.com/mattermost/mattermost-server/utils"
+	xfont "golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
 )
 
 const (
 	TOKEN_TYPE_PASSWORD_RECOVERY  = "password_recovery"
 	TOKEN_TYPE_VERIFY_EMAIL       = "verify_email"
 	TOKEN_TYPE_TEAM_INVITATION    = "team_invitation"
+	TOKEN_TYPE_GUEST_INVITATION   = "guest_invitation"
 	PASSWORD_RECOVER_EXPIRY_TIME  = 1000 * 60 * 60      // 1 hour
 	TEAM_INVITATION_EXPIRY_TIME   = 1000 * 60 * 60 * 48 // 48 hours
 	IMAGE_PROFILE_PIXEL_DIMENSION = 128
@@ -51,7 +56,7 @@ func (a *App) CreateUserWithToken(user *model.User, tokenId string) (*model.User
 	}
 
 	token := result.Data.(*model.Token)
-	if token.Type != TOKEN_TYPE_TEAM_INVITATION {
+	if token.Type != TOKEN_TYPE_TEAM_INVITATION && token.Type != TOKEN_TYPE_GUEST_INVITATION {
 		return nil, model.NewAppError("CreateUserWithToken", "api.user.create_user.signup_link_invalid.app_error", nil, "", http.StatusBadRequest)
 	}
 
@@ -74,7 +79,15 @@ func (a *App) CreateUserWithToken(user *model.User, tokenId string) (*model.User
 
 	var ruser *model.User
 	var err *model.AppError
-	if ruser, err = a.CreateUser(user); err != nil {
+	if token.Type == TOKEN_TYPE_GUEST_INVITATION {
+		if !*a.Config().GuestAccountsSettings.Enable {
+			return nil, model.NewAppError("CreateUserWithToken", "api.user.create_user.guest_accounts.disabled.app_error", nil, "", http.StatusNotImplemented)
+		}
+
+		if ruser, err = a.CreateGuest(user); err != nil {
+			return nil, err
+		}
+	} else if ruser, err = a.CreateUser(user); err != nil {
 		return nil, err
 	}
 
@@ -82,7 +95,11 @@ func (a *App) CreateUserWithToken(user *model.User, tokenId string) (*model.User
 		return nil, err
 	}
 
-	a.AddDirectChannels(team.Id, ruser)
+	a.joinUserToInvitedChannels(team, ruser, tokenData["channels"])
+
+	if token.Type != TOKEN_TYPE_GUEST_INVITATION {
+		a.AddDirectChannels(team.Id, ruser)
+	}
 
 	if err := a.DeleteToken(token); err != nil {
 		return nil, err
@@ -96,17 +113,14 @@ func (a *App) CreateUserWithInviteId(user *model.User, inviteId string) (*model.
 		return nil, err
 	}
 
-	var team *model.Team
-	if result := <-a.Srv.Store.Team().GetByInviteId(inviteId); result.Err != nil {
-		return nil, result.Err
-	} else {
-		team = result.Data.(*model.Team)
+	team, err := a.GetTeamByInviteId(inviteId)
+	if err != nil {
+		return nil, err
 	}
 
 	user.EmailVerified = false
 
 	var ruser *model.User
-	var err *model.AppError
 	if ruser, err = a.CreateUser(user); err != nil {
 		return nil, err
 	}
@@ -203,6 +217,18 @@ func (a *App) CreateUser(user *model.User) (*model.User, *model.AppError) {
 		}
 	}
 
+	return a.createUserAndPublish(user)
+}
+
+// CreateGuest creates a user with the system guest role. Guests can only see the channels they've
+// been added to and the members of those channels.
+func (a *App) CreateGuest(user *model.User) (*model.User, *model.AppError) {
+	user.Roles = model.SYSTEM_GUEST_ROLE_ID
+
+	return a.createUserAndPublish(user)
+}
+
+func (a *App) createUserAndPublish(user *model.User) (*model.User, *model.AppError) {
 	if _, ok := utils.GetSupportedLocales()[user.Locale]; !ok {
 		user.Locale = *a.Config().LocalizationSettings.DefaultClientLocale
 	}
@@ -221,11 +247,16 @@ func (a *App) CreateUser(user *model.User) (*model.User, *model.AppError) {
 
 func (a *App) createUser(user *model.User) (*model.User, *model.AppError) {
 	user.MakeNonNil()
+	user.IsBot = false
 
 	if err := a.IsPasswordValid(user.Password); user.AuthService == "" && err != nil {
 		return nil, err
 	}
 
+	if err := a.checkUsernamePolicy(user, nil); err != nil {
+		return nil, err
+	}
+
 	if result := <-a.Srv.Store.User().Save(user); result.Err != nil {
 		mlog.Error(fmt.Sprintf("Couldn't save the user err=%v", result.Err))
 		return nil, result.Err
@@ -415,6 +446,32 @@ func (a *App) GetUsersPage(page int, perPage int, asAdmin bool) ([]*model.User,
 	return a.sanitizeProfiles(users, asAdmin), nil
 }
 
+func (a *App) GetUsersWithFilterPage(filter *model.UserFilter, page int, perPage int, asAdmin bool) ([]*model.User, *model.AppError) {
+	if err := filter.IsValid(); err != nil {
+		return nil, err
+	}
+
+	result := <-a.Srv.Store.User().GetProfilesWithFilter(filter, page*perPage, perPage)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return a.sanitizeProfiles(result.Data.([]*model.User), asAdmin), nil
+}
+
+func (a *App) GetUsersCountWithFilter(filter *model.UserFilter) (int64, *model.AppError) {
+	if err := filter.IsValid(); err != nil {
+		return 0, err
+	}
+
+	result := <-a.Srv.Store.User().CountWithFilter(filter)
+	if result.Err != nil {
+		return 0, result.Err
+	}
+
+	return result.Data.(int64), nil
+}
+
 func (a *App) GetUsersEtag() string {
 	return fmt.Sprintf("%v.%v.%v", (<-a.Srv.Store.User().GetEtagForAllProfiles()).Data.(string), a.Config().PrivacySettings.ShowFullName, a.Config().PrivacySettings.ShowEmailAddress)
 }
@@ -660,42 +717,46 @@ func (a *App) DeactivateMfa(userId string) *model.AppError {
 	return nil
 }
 
-func CreateProfileImage(username string, userId string, initialFont string) ([]byte, *model.AppError) {
-	colors := []color.NRGBA{
-		{197, 8, 126, 255},
-		{227, 207, 18, 255},
-		{28, 181, 105, 255},
-		{35, 188, 224, 255},
-		{116, 49, 196, 255},
-		{197, 8, 126, 255},
-		{197, 19, 19, 255},
-		{250, 134, 6, 255},
-		{227, 207, 18, 255},
-		{123, 201, 71, 255},
-		{28, 181, 105, 255},
-		{35, 188, 224, 255},
-		{116, 49, 196, 255},
-		{197, 8, 126, 255},
-		{197, 19, 19, 255},
-		{250, 134, 6, 255},
-		{227, 207, 18, 255},
-		{123, 201, 71, 255},
-		{28, 181, 105, 255},
-		{35, 188, 224, 255},
-		{116, 49, 196, 255},
-		{197, 8, 126, 255},
-		{197, 19, 19, 255},
-		{250, 134, 6, 255},
-		{227, 207, 18, 255},
-		{123, 201, 71, 255},
-	}
-
+var profileImageColors = []color.NRGBA{
+	{197, 8, 126, 255},
+	{227, 207, 18, 255},
+	{28, 181, 105, 255},
+	{35, 188, 224, 255},
+	{116, 49, 196, 255},
+	{197, 8, 126, 255},
+	{197, 19, 19, 255},
+	{250, 134, 6, 255},
+	{227, 207, 18, 255},
+	{123, 201, 71, 255},
+	{28, 181, 105, 255},
+	{35, 188, 224, 255},
+	{116, 49, 196, 255},
+	{197, 8, 126, 255},
+	{197, 19, 19, 255},
+	{250, 134, 6, 255},
+	{227, 207, 18, 255},
+	{123, 201, 71, 255},
+	{28, 181, 105, 255},
+	{35, 188, 224, 255},
+	{116, 49, 196, 255},
+	{197, 8, 126, 255},
+	{197, 19, 19, 255},
+	{250, 134, 6, 255},
+	{227, 207, 18, 255},
+	{123, 201, 71, 255},
+}
+
+// getProfileImageColor returns the background color of the default profile images for the user, which is the same
+// every time for them.
+func getProfileImageColor(userId string) color.NRGBA {
 	h := fnv.New32a()
 	h.Write([]byte(userId))
 	seed := h.Sum32()
 
-	initial := string(strings.ToUpper(username)[0])
+	return profileImageColors[int64(seed)%int64(len(profileImageColors))]
+}
 
+func loadProfileImageFont(initialFont string) (*truetype.Font, *model.AppError) {
 	fontDir, _ := utils.FindDir("fonts")
 	fontBytes, err := ioutil.ReadFile(filepath.Join(fontDir, initialFont))
 	if err != nil {
@@ -706,7 +767,18 @@ func CreateProfileImage(username string, userId string, initialFont string) ([]b
 		return nil, model.NewAppError("CreateProfileImage", "api.user.create_profile_image.default_font.app_error", nil, err.Error(), http.StatusInternalServerError)
 	}
 
-	color := colors[int64(seed)%int64(len(colors))]
+	return font, nil
+}
+
+func CreateProfileImage(username string, userId string, initialFont string) ([]byte, *model.AppError) {
+	initial := string(strings.ToUpper(username)[0])
+
+	font, err := loadProfileImageFont(initialFont)
+	if err != nil {
+		return nil, err
+	}
+
+	color := getProfileImageColor(userId)
 	dstImg := image.NewRGBA(image.Rect(0, 0, IMAGE_PROFILE_PIXEL_DIMENSION, IMAGE_PROFILE_PIXEL_DIMENSION))
 	srcImg := image.White
 	draw.Draw(dstImg, dstImg.Bounds(), &image.Uniform{color}, image.ZP, draw.Src)
@@ -720,9 +792,8 @@ func CreateProfileImage(username string, userId string, initialFont string) ([]b
 	c.SetSrc(srcImg)
 
 	pt := freetype.Pt(IMAGE_PROFILE_PIXEL_DIMENSION/6, IMAGE_PROFILE_PIXEL_DIMENSION*2/3)
-	_, err = c.DrawString(initial, pt)
-	if err != nil {
-		return nil, model.NewAppError("CreateProfileImage", "api.user.create_profile_image.initial.app_error", nil, err.Error(), http.StatusInternalServerError)
+	if _, drawErr := c.DrawString(initial, pt); drawErr != nil {
+		return nil, model.NewAppError("CreateProfileImage", "api.user.create_profile_image.initial.app_error", nil, drawErr.Error(), http.StatusInternalServerError)
 	}
 
 	buf := new(bytes.Buffer)
@@ -734,7 +805,107 @@ func CreateProfileImage(username string, userId string, initialFont string) ([]b
 	}
 }
 
+// CreateInitialsProfileImage returns a default profile image with the initials centered on the user's color.
+func CreateInitialsProfileImage(initials string, userId string, initialFont string) ([]byte, *model.AppError) {
+	font, err := loadProfileImageFont(initialFont)
+	if err != nil {
+		return nil, err
+	}
+
+	dstImg := image.NewRGBA(image.Rect(0, 0, IMAGE_PROFILE_PIXEL_DIMENSION, IMAGE_PROFILE_PIXEL_DIMENSION))
+	draw.Draw(dstImg, dstImg.Bounds(), &image.Uniform{getProfileImageColor(userId)}, image.ZP, draw.Src)
+
+	// Two letters are drawn smaller than one so that they both fit
+	size := float64(IMAGE_PROFILE_PIXEL_DIMENSION / 2)
+	if utf8.RuneCountInString(initials) > 1 {
+		size = float64(IMAGE_PROFILE_PIXEL_DIMENSION * 2 / 5)
+	}
+
+	drawer := &xfont.Drawer{
+		Dst:  dstImg,
+		Src:  image.White,
+		Face: truetype.NewFace(font, &truetype.Options{Size: size}),
+	}
+
+	bounds, advance := drawer.BoundString(initials)
+	dimension := fixed.I(IMAGE_PROFILE_PIXEL_DIMENSION)
+	drawer.Dot = fixed.Point26_6{
+		X: (dimension - advance) / 2,
+		Y: (dimension-(bounds.Max.Y-bounds.Min.Y))/2 - bounds.Min.Y,
+	}
+	drawer.DrawString(initials)
+
+	buf := new(bytes.Buffer)
+	if imgErr := png.Encode(buf, dstImg); imgErr != nil {
+		return nil, model.NewAppError("CreateInitialsProfileImage", "api.user.create_profile_image.encode.app_error", nil, imgErr.Error(), http.StatusInternalServerError)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// usesInitialsProfileImage returns true if the user is shown with a profile image generated from their initials.
+func (a *App) usesInitialsProfileImage(user *model.User) bool {
+	return user.LastPictureUpdate == 0 && *a.Config().FileSettings.EnableProfileImageInitials
+}
+
+// getProfileImageInitialsKey returns a key that changes whenever the user's generated profile image would.
+func (a *App) getProfileImageInitialsKey(user *model.User) string {
+	h := fnv.New32a()
+	h.Write([]byte(user.GetInitials(*a.Config().TeamSettings.TeammateNameDisplay)))
+	h.Write([]byte{0})
+	h.Write([]byte(a.Config().FileSettings.InitialFont))
+
+	return strconv.FormatUint(uint64(h.Sum32()), 16)
+}
+
+// GetProfileImageEtag returns the etag of the user's profile image. The etag of an image generated from the user's
+// initials changes along with them, so it's updated when the user's name is.
+func (a *App) GetProfileImageEtag(user *model.User) string {
+	etag := strconv.FormatInt(user.LastPictureUpdate, 10)
+	if a.usesInitialsProfileImage(user) {
+		etag += "." + a.getProfileImageInitialsKey(user)
+	}
+
+	return etag
+}
+
+// getInitialsProfileImage returns the profile image with the user's initials, which is kept in the file store until
+// their initials change.
+func (a *App) getInitialsProfileImage(user *model.User) ([]byte, *model.AppError) {
+	initials := user.GetInitials(*a.Config().TeamSettings.TeammateNameDisplay)
+
+	if len(*a.Config().FileSettings.DriverName) == 0 {
+		return CreateInitialsProfileImage(initials, user.Id, a.Config().FileSettings.InitialFont)
+	}
+
+	dir := "users/" + user.Id + "/initials/"
+	path := dir + a.getProfileImageInitialsKey(user) + ".png"
+	if data, err := a.ReadFile(path); err == nil {
+		return data, nil
+	}
+
+	img, err := CreateInitialsProfileImage(initials, user.Id, a.Config().FileSettings.InitialFont)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the image for the user's current initials is kept
+	if err := a.RemoveDirectory(dir); err != nil {
+		mlog.Warn(fmt.Sprintf("Failed to remove old profile images: %v", err.Error()), mlog.String("user_id", user.Id))
+	}
+	if _, err := a.WriteFile(bytes.NewReader(img), path); err != nil {
+		mlog.Warn(fmt.Sprintf("Failed to save a profile image: %v", err.Error()), mlog.String("user_id", user.Id))
+	}
+
+	return img, nil
+}
+
 func (a *App) GetProfileImage(user *model.User) ([]byte, bool, *model.AppError) {
+	if a.usesInitialsProfileImage(user) {
+		img, err := a.getInitialsProfileImage(user)
+		return img, false, err
+	}
+
 	var img []byte
 	readFailed := false
 
@@ -749,7 +920,11 @@ func (a *App) GetProfileImage(user *model.User) ([]byte, bool, *model.AppError)
 		if data, err := a.ReadFile(path); err != nil {
 			readFailed = true
 
-			if img, err = CreateProfileImage(user.Username, user.Id, a.Config().FileSettings.InitialFont); err != nil {
+			if *a.Config().FileSettings.EnableProfileImageInitials {
+				if img, err = a.getInitialsProfileImage(user); err != nil {
+					return nil, false, err
+				}
+			} else if img, err = CreateProfileImage(user.Username, user.Id, a.Config().FileSettings.InitialFont); err != nil {
 				return nil, false, err
 			}
 
@@ -879,6 +1054,7 @@ func (a *App) UpdateNonSSOUserActive(userId string, active bool) (*model.User, *
 }
 
 func (a *App) UpdateActive(user *model.User, active bool) (*model.User, *model.AppError) {
+	user.DeactivateAt = 0
 	if active {
 		user.DeleteAt = 0
 	} else {
@@ -903,6 +1079,16 @@ func (a *App) UpdateActive(user *model.User, active bool) (*model.User, *model.A
 			a.SetStatusOffline(ruser.Id, false)
 		}
 
+		if active {
+			if err := a.restoreDirectChannelsForUser(user.Id); err != nil {
+				mlog.Error("Unable to restore direct channels of reactivated user", mlog.String("user_id", user.Id), mlog.String("error", err.Error()))
+			}
+		} else if *a.Config().DeactivationSettings.ArchiveDirectChannels {
+			if err := a.archiveDirectChannelsForUser(user.Id); err != nil {
+				mlog.Error("Unable to archive direct channels of deactivated user", mlog.String("user_id", user.Id), mlog.String("error", err.Error()))
+			}
+		}
+
 		teamsForUser, err := a.GetTeamsForUser(user.Id)
 		if err != nil {
 			return nil, err
@@ -931,16 +1117,29 @@ func (a *App) SanitizeProfile(user *model.User, asAdmin bool) {
 		options["email"] = true
 		options["fullname"] = true
 		options["authservice"] = true
+		options["deactivateat"] = true
+		options["lastseenat"] = true
 	}
 	user.SanitizeProfile(options)
 }
 
 func (a *App) UpdateUserAsUser(user *model.User, asAdmin bool) (*model.User, *model.AppError) {
+	newEmail, err := a.holdEmailChange(user, asAdmin)
+	if err != nil {
+		return nil, err
+	}
+
 	updatedUser, err := a.UpdateUser(user, true)
 	if err != nil {
 		return nil, err
 	}
 
+	if newEmail != "" {
+		if err := a.requestEmailChange(updatedUser, newEmail); err != nil {
+			return nil, err
+		}
+	}
+
 	a.sendUpdatedUserEvent(*updatedUser)
 
 	return updatedUser, nil
@@ -954,11 +1153,22 @@ func (a *App) PatchUser(userId string, patch *model.UserPatch, asAdmin bool) (*m
 
 	user.Patch(patch)
 
+	newEmail, err := a.holdEmailChange(user, asAdmin)
+	if err != nil {
+		return nil, err
+	}
+
 	updatedUser, err := a.UpdateUser(user, true)
 	if err != nil {
 		return nil, err
 	}
 
+	if newEmail != "" {
+		if err := a.requestEmailChange(updatedUser, newEmail); err != nil {
+			return nil, err
+		}
+	}
+
 	a.sendUpdatedUserEvent(*updatedUser)
 
 	return updatedUser, nil
@@ -1015,11 +1225,24 @@ func (a *App) UpdateUser(user *model.User, sendNotifications bool) (*model.User,
 		}
 	}
 
+	if *a.Config().UsernamePolicySettings.Enable {
+		result := <-a.Srv.Store.User().Get(user.Id)
+		if result.Err != nil {
+			return nil, result.Err
+		}
+
+		if err := a.checkUsernamePolicy(user, result.Data.(*model.User)); err != nil {
+			return nil, err
+		}
+	}
+
 	if result := <-a.Srv.Store.User().Update(user, false); result.Err != nil {
 		return nil, result.Err
 	} else {
 		rusers := result.Data.([2]*model.User)
 
+		a.updateUsernameHistory(rusers[0], rusers[1].Username)
+
 		if sendNotifications {
 			if rusers[0].Email != rusers[1].Email {
 				a.Go(func() {
@@ -1089,7 +1312,7 @@ func (a *App) UpdateMfa(activate bool, userId, token string) *model.AppError {
 			return
 		}
 
-		if err := a.SendMfaChangeEmail(user.Email, activate, user.Locale, a.GetSiteURL()); err != nil {
+		if err := a.SendMfaChangeEmail(user.Email, activate, user.Locale, a.GetSiteURL(), a.GetUserTimezoneLocation(user)); err != nil {
 			mlog.Error(err.Error())
 		}
 	})
@@ -1127,7 +1350,7 @@ func (a *App) UpdatePasswordSendEmail(user *model.User, newPassword, method stri
 	}
 
 	a.Go(func() {
-		if err := a.SendPasswordChangeEmail(user.Email, method, user.Locale, a.GetSiteURL()); err != nil {
+		if err := a.SendPasswordChangeEmail(user.Email, method, user.Locale, a.GetSiteURL(), a.GetUserTimezoneLocation(user)); err != nil {
 			mlog.Error(err.Error())
 		}
 	})
@@ -1303,6 +1526,14 @@ func (a *App) PermanentDeleteUser(user *model.User) *model.AppError {
 		return result.Err
 	}
 
+	if result := <-a.Srv.Store.CustomGroup().PermanentDeleteMembersByUser(user.Id); result.Err != nil {
+		return result.Err
+	}
+
+	if result := <-a.Srv.Store.UserAttribute().PermanentDeleteByUser(user.Id); result.Err != nil {
+		return result.Err
+	}
+
 	if result := <-a.Srv.Store.Post().PermanentDeleteByUser(user.Id); result.Err != nil {
 		return result.Err
 	}
@@ -1356,6 +1587,9 @@ func (a *App) VerifyEmailFromToken(userSuppliedTokenString string) *model.AppErr
 	if token, err = a.GetVerifyEmailToken(userSuppliedTokenString); err != nil {
 		return err
 	} else {
+		if token.Type == TOKEN_TYPE_EMAIL_CHANGE {
+			return a.confirmEmailChange(token)
+		}
 		if model.GetMillis()-token.CreateAt >= PASSWORD_RECOVER_EXPIRY_TIME {
 			return model.NewAppError("resetPassword", "api.user.reset_password.link_expired.app_error", nil, "", http.StatusBadRequest)
 		}
@@ -1385,7 +1619,7 @@ func (a *App) GetVerifyEmailToken(token string) (*model.Token, *model.AppError)
 		return nil, model.NewAppError("GetVerifyEmailToken", "api.user.verify_email.bad_link.app_error", nil, result.Err.Error(), http.StatusBadRequest)
 	} else {
 		token := result.Data.(*model.Token)
-		if token.Type != TOKEN_TYPE_VERIFY_EMAIL {
+		if token.Type != TOKEN_TYPE_VERIFY_EMAIL && token.Type != TOKEN_TYPE_EMAIL_CHANGE {
 			return nil, model.NewAppError("GetVerifyEmailToken", "api.user.verify_email.broken_token.app_error", nil, "", http.StatusBadRequest)
 		}
 		return token, nil
@@ -1541,7 +1775,10 @@ func (a *App) UpdateOAuthUserAttrs(userData io.Reader, user *model.User, provide
 	userAttrsChanged := false
 
 	if oauthUser.Username != user.Username {
-		if existingUser, _ := a.GetUserByUsername(oauthUser.Username); existingUser == nil {
+		policy := a.Config().UsernamePolicySettings
+		if *policy.Enable && usernamePolicyViolation(&policy, oauthUser.Username) != "" {
+			mlog.Warn(fmt.Sprintf("Not updating the username of user_id=%v because %v breaks the username policy", user.Id, oauthUser.Username))
+		} else if existingUser, _ := a.GetUserByUsername(oauthUser.Username); existingUser == nil {
 			user.Username = oauthUser.Username
 			userAttrsChanged = true
 		}
@@ -0,0 +1,280 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func (a *App) CreateUserAttributeField(field *model.UserAttributeField) (*model.UserAttributeField, *model.AppError) {
+	field.Id = ""
+
+	result := <-a.Srv.Store.UserAttribute().SaveField(field)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.UserAttributeField), nil
+}
+
+func (a *App) GetUserAttributeField(fieldId string) (*model.UserAttributeField, *model.AppError) {
+	result := <-a.Srv.Store.UserAttribute().GetField(fieldId)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.(*model.UserAttributeField), nil
+}
+
+func (a *App) GetUserAttributeFields() ([]*model.UserAttributeField, *model.AppError) {
+	result := <-a.Srv.Store.UserAttribute().GetFields()
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.([]*model.UserAttributeField), nil
+}
+
+func (a *App) getUserAttributeFieldsById() (map[string]*model.UserAttributeField, *model.AppError) {
+	fields, err := a.GetUserAttributeFields()
+	if err != nil {
+		return nil, err
+	}
+
+	fieldsById := make(map[string]*model.UserAttributeField, len(fields))
+	for _, field := range fields {
+		fieldsById[field.Id] = field
+	}
+
+	return fieldsById, nil
+}
+
+// PatchUserAttributeField updates the field's schema. Any values that users have for the field that no longer fit it,
+// such as a select option that was removed, are deleted.
+func (a *App) PatchUserAttributeField(field *model.UserAttributeField, patch *model.UserAttributeFieldPatch) (*model.UserAttributeField, *model.AppError) {
+	field.Patch(patch)
+
+	result := <-a.Srv.Store.UserAttribute().UpdateField(field)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	field = result.Data.(*model.UserAttributeField)
+
+	if patch.Type != nil || patch.Options != nil {
+		if err := a.removeStaleUserAttributes(field); err != nil {
+			return nil, err
+		}
+	}
+
+	return field, nil
+}
+
+func (a *App) removeStaleUserAttributes(field *model.UserAttributeField) *model.AppError {
+	result := <-a.Srv.Store.UserAttribute().GetValuesForField(field.Id)
+	if result.Err != nil {
+		return result.Err
+	}
+
+	var userIds []string
+	for _, attribute := range result.Data.([]*model.UserAttribute) {
+		if field.IsValidValue(attribute.Value) == nil {
+			continue
+		}
+
+		if result := <-a.Srv.Store.UserAttribute().DeleteValue(attribute.UserId, field.Id); result.Err != nil {
+			return result.Err
+		}
+		userIds = append(userIds, attribute.UserId)
+	}
+
+	a.invalidateUsersWithChangedAttributes(userIds)
+
+	return nil
+}
+
+func (a *App) DeleteUserAttributeField(fieldId string) *model.AppError {
+	result := <-a.Srv.Store.UserAttribute().GetValuesForField(fieldId)
+	if result.Err != nil {
+		return result.Err
+	}
+	attributes := result.Data.([]*model.UserAttribute)
+
+	if result := <-a.Srv.Store.UserAttribute().DeleteField(fieldId); result.Err != nil {
+		return result.Err
+	}
+
+	userIds := make([]string, len(attributes))
+	for i, attribute := range attributes {
+		userIds[i] = attribute.UserId
+	}
+	a.invalidateUsersWithChangedAttributes(userIds)
+
+	return nil
+}
+
+// invalidateUsersWithChangedAttributes updates the users so that their profiles aren't served from caches that still
+// have their old attributes.
+func (a *App) invalidateUsersWithChangedAttributes(userIds []string) {
+	for _, userId := range userIds {
+		if result := <-a.Srv.Store.User().UpdateUpdateAt(userId); result.Err != nil {
+			mlog.Warn("Failed to update user after changing their attributes", mlog.String("user_id", userId), mlog.Err(result.Err))
+		}
+
+		a.InvalidateCacheForUser(userId)
+	}
+}
+
+// GetUserAttributes returns the user's attribute values keyed by field id. Private values are only included if
+// includePrivate is true, which should only be the case for the user themselves and for system admins.
+func (a *App) GetUserAttributes(userId string, includePrivate bool) (model.StringMap, *model.AppError) {
+	fields, err := a.getUserAttributeFieldsById()
+	if err != nil {
+		return nil, err
+	}
+
+	result := <-a.Srv.Store.UserAttribute().GetValuesForUser(userId)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	values := model.StringMap{}
+	for _, attribute := range result.Data.([]*model.UserAttribute) {
+		if field, ok := fields[attribute.FieldId]; ok && field.IsVisibleTo(includePrivate) {
+			values[attribute.FieldId] = attribute.Value
+		}
+	}
+
+	return values, nil
+}
+
+// UpdateUserAttributes sets the given attribute values for the user, keyed by field id. Fields that aren't included
+// are left as they are, and fields that are set to an empty string are cleared. Read only fields can only be
+// changed by system admins.
+func (a *App) UpdateUserAttributes(userId string, values model.StringMap, asAdmin bool) (model.StringMap, *model.AppError) {
+	fields, err := a.getUserAttributeFieldsById()
+	if err != nil {
+		return nil, err
+	}
+
+	for fieldId, value := range values {
+		field, ok := fields[fieldId]
+		if !ok {
+			return nil, model.NewAppError("UpdateUserAttributes", "app.user_attribute.unknown_field.app_error", nil, "field_id="+fieldId, http.StatusBadRequest)
+		}
+
+		if field.ReadOnly && !asAdmin {
+			return nil, model.NewAppError("UpdateUserAttributes", "app.user_attribute.update.read_only.app_error", map[string]interface{}{"Name": field.Name}, "field_id="+fieldId, http.StatusForbidden)
+		}
+
+		if value != "" {
+			if err := field.IsValidValue(value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for fieldId, value := range values {
+		if value == "" {
+			if result := <-a.Srv.Store.UserAttribute().DeleteValue(userId, fieldId); result.Err != nil {
+				return nil, result.Err
+			}
+		} else if result := <-a.Srv.Store.UserAttribute().SaveValue(&model.UserAttribute{UserId: userId, FieldId: fieldId, Value: value}); result.Err != nil {
+			return nil, result.Err
+		}
+	}
+
+	a.invalidateUsersWithChangedAttributes([]string{userId})
+
+	if user, err := a.GetUser(userId); err == nil {
+		a.sendUpdatedUserEvent(*user)
+	}
+
+	return a.GetUserAttributes(userId, true)
+}
+
+// SetUserAttributesOnProfiles fills in the attributes of each user that can be seen by the viewer.
+func (a *App) SetUserAttributesOnProfiles(users []*model.User, viewerId string, asAdmin bool) *model.AppError {
+	if len(users) == 0 {
+		return nil
+	}
+
+	fields, err := a.getUserAttributeFieldsById()
+	if err != nil {
+		return err
+	} else if len(fields) == 0 {
+		return nil
+	}
+
+	usersById := make(map[string]*model.User, len(users))
+	userIds := make([]string, len(users))
+	for i, user := range users {
+		usersById[user.Id] = user
+		userIds[i] = user.Id
+		user.Attributes = nil
+	}
+
+	result := <-a.Srv.Store.UserAttribute().GetValuesForUsers(userIds)
+	if result.Err != nil {
+		return result.Err
+	}
+
+	for _, attribute := range result.Data.([]*model.UserAttribute) {
+		field, ok := fields[attribute.FieldId]
+		if !ok || !field.IsVisibleTo(asAdmin || attribute.UserId == viewerId) {
+			continue
+		}
+
+		user := usersById[attribute.UserId]
+		if user.Attributes == nil {
+			user.Attributes = model.StringMap{}
+		}
+		user.Attributes[attribute.FieldId] = attribute.Value
+	}
+
+	return nil
+}
+
+// FilterUsersByAttributes returns the users whose attributes match all of the given values, keyed by field id,
+// ignoring case. Only system admins can filter by private fields. The users' visible attributes are filled in as
+// well. Since this filters users that have already been found, searches that are filtered by attributes may return
+// fewer users than their limit.
+func (a *App) FilterUsersByAttributes(users []*model.User, filters model.StringMap, viewerId string, asAdmin bool) ([]*model.User, *model.AppError) {
+	if len(filters) > 0 {
+		fields, err := a.getUserAttributeFieldsById()
+		if err != nil {
+			return nil, err
+		}
+
+		for fieldId := range filters {
+			if field, ok := fields[fieldId]; !ok || !field.IsVisibleTo(asAdmin) {
+				return nil, model.NewAppError("FilterUsersByAttributes", "app.user_attribute.unknown_field.app_error", nil, "field_id="+fieldId, http.StatusBadRequest)
+			}
+		}
+	}
+
+	if err := a.SetUserAttributesOnProfiles(users, viewerId, asAdmin); err != nil {
+		return nil, err
+	}
+
+	filtered := make([]*model.User, 0, len(users))
+	for _, user := range users {
+		matches := true
+		for fieldId, value := range filters {
+			if !strings.EqualFold(user.Attributes[fieldId], value) {
+				matches = false
+				break
+			}
+		}
+
+		if matches {
+			filtered = append(filtered, user)
+		}
+	}
+
+	return filtered, nil
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func (me *TestHelper) createUserAttributeField(field *model.UserAttributeField) *model.UserAttributeField {
+	if field.Name == "" {
+		field.Name = "Field " + model.NewId()
+	}
+
+	field, err := me.App.CreateUserAttributeField(field)
+	if err != nil {
+		panic(err)
+	}
+
+	return field
+}
+
+func TestUpdateUserAttributes(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	office := th.createUserAttributeField(&model.UserAttributeField{Type: model.USER_ATTRIBUTE_TYPE_SELECT, Options: model.StringArray{"Berlin", "Toronto"}})
+	employeeId := th.createUserAttributeField(&model.UserAttributeField{Type: model.USER_ATTRIBUTE_TYPE_TEXT, ReadOnly: true})
+	salary := th.createUserAttributeField(&model.UserAttributeField{Type: model.USER_ATTRIBUTE_TYPE_TEXT, Visibility: model.USER_ATTRIBUTE_VISIBILITY_PRIVATE})
+
+	values, err := th.App.UpdateUserAttributes(th.BasicUser.Id, model.StringMap{office.Id: "Berlin", salary.Id: "100"}, false)
+	require.Nil(t, err)
+	assert.Equal(t, model.StringMap{office.Id: "Berlin", salary.Id: "100"}, values)
+
+	_, err = th.App.UpdateUserAttributes(th.BasicUser.Id, model.StringMap{office.Id: "Paris"}, false)
+	require.NotNil(t, err)
+	assert.Equal(t, "model.user_attribute_field.is_valid_value.option.app_error", err.Id)
+
+	_, err = th.App.UpdateUserAttributes(th.BasicUser.Id, model.StringMap{model.NewId(): "value"}, false)
+	require.NotNil(t, err)
+	assert.Equal(t, "app.user_attribute.unknown_field.app_error", err.Id)
+
+	_, err = th.App.UpdateUserAttributes(th.BasicUser.Id, model.StringMap{employeeId.Id: "E1"}, false)
+	require.NotNil(t, err)
+	assert.Equal(t, http.StatusForbidden, err.StatusCode)
+
+	values, err = th.App.UpdateUserAttributes(th.BasicUser.Id, model.StringMap{employeeId.Id: "E1", salary.Id: ""}, true)
+	require.Nil(t, err)
+	assert.Equal(t, model.StringMap{office.Id: "Berlin", employeeId.Id: "E1"}, values)
+
+	values, err = th.App.GetUserAttributes(th.BasicUser.Id, false)
+	require.Nil(t, err)
+	assert.Equal(t, model.StringMap{office.Id: "Berlin", employeeId.Id: "E1"}, values)
+}
+
+func TestPatchUserAttributeFieldRemovesStaleValues(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	office := th.createUserAttributeField(&model.UserAttributeField{Type: model.USER_ATTRIBUTE_TYPE_SELECT, Options: model.StringArray{"Berlin", "Toronto"}})
+	_, err := th.App.UpdateUserAttributes(th.BasicUser.Id, model.StringMap{office.Id: "Berlin"}, false)
+	require.Nil(t, err)
+	_, err = th.App.UpdateUserAttributes(th.BasicUser2.Id, model.StringMap{office.Id: "Toronto"}, false)
+	require.Nil(t, err)
+
+	before, err := th.App.GetUser(th.BasicUser.Id)
+	require.Nil(t, err)
+	time.Sleep(time.Millisecond)
+
+	options := model.StringArray{"Toronto", "Paris"}
+	_, err = th.App.PatchUserAttributeField(office, &model.UserAttributeFieldPatch{Options: &options})
+	require.Nil(t, err)
+
+	values, err := th.App.GetUserAttributes(th.BasicUser.Id, true)
+	require.Nil(t, err)
+	assert.Empty(t, values, "should've removed the value that's no longer an option")
+
+	values, err = th.App.GetUserAttributes(th.BasicUser2.Id, true)
+	require.Nil(t, err)
+	assert.Equal(t, model.StringMap{office.Id: "Toronto"}, values)
+
+	after, err := th.App.GetUser(th.BasicUser.Id)
+	require.Nil(t, err)
+	assert.True(t, after.UpdateAt > before.UpdateAt, "should've updated the user so that their etag changes")
+
+	// Changing the type removes any values that don't fit the new one
+	fieldType := model.USER_ATTRIBUTE_TYPE_DATE
+	_, err = th.App.PatchUserAttributeField(office, &model.UserAttributeFieldPatch{Type: &fieldType, Options: &model.StringArray{}})
+	require.Nil(t, err)
+
+	values, err = th.App.GetUserAttributes(th.BasicUser2.Id, true)
+	require.Nil(t, err)
+	assert.Empty(t, values)
+}
+
+func TestFilterUsersByAttributes(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	department := th.createUserAttributeField(&model.UserAttributeField{Type: model.USER_ATTRIBUTE_TYPE_TEXT})
+	salary := th.createUserAttributeField(&model.UserAttributeField{Type: model.USER_ATTRIBUTE_TYPE_TEXT, Visibility: model.USER_ATTRIBUTE_VISIBILITY_PRIVATE})
+
+	_, err := th.App.UpdateUserAttributes(th.BasicUser.Id, model.StringMap{department.Id: "Engineering", salary.Id: "100"}, false)
+	require.Nil(t, err)
+	_, err = th.App.UpdateUserAttributes(th.BasicUser2.Id, model.StringMap{department.Id: "Sales", salary.Id: "200"}, false)
+	require.Nil(t, err)
+
+	users := func() []*model.User {
+		users, err := th.App.GetUsersByIds([]string{th.BasicUser.Id, th.BasicUser2.Id}, false)
+		require.Nil(t, err)
+		return users
+	}
+
+	filtered, err := th.App.FilterUsersByAttributes(users(), model.StringMap{department.Id: "engineering"}, th.BasicUser2.Id, false)
+	require.Nil(t, err)
+	require.Len(t, filtered, 1)
+	assert.Equal(t, th.BasicUser.Id, filtered[0].Id)
+	assert.Equal(t, model.StringMap{department.Id: "Engineering"}, filtered[0].Attributes, "shouldn't include private attributes")
+
+	filtered, err = th.App.FilterUsersByAttributes(users(), model.StringMap{}, th.BasicUser2.Id, false)
+	require.Nil(t, err)
+	assert.Len(t, filtered, 2)
+	for _, user := range filtered {
+		if user.Id == th.BasicUser2.Id {
+			assert.Equal(t, "200", user.Attributes[salary.Id], "should include the viewer's own private attributes")
+		}
+	}
+
+	_, err = th.App.FilterUsersByAttributes(users(), model.StringMap{salary.Id: "100"}, th.BasicUser2.Id, false)
+	require.NotNil(t, err)
+	assert.Equal(t, "app.user_attribute.unknown_field.app_error", err.Id)
+
+	filtered, err = th.App.FilterUsersByAttributes(users(), model.StringMap{salary.Id: "100"}, th.BasicUser2.Id, true)
+	require.Nil(t, err)
+	require.Len(t, filtered, 1)
+	assert.Equal(t, th.BasicUser.Id, filtered[0].Id)
+}
@@ -0,0 +1,193 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// BlockUser adds blockedUserId to userId's block list. Blocked users can't start new direct messages with the user,
+// and their posts don't notify the user or count as mentions of them, but they're still shown in channels.
+func (a *App) BlockUser(userId string, blockedUserId string) *model.AppError {
+	if err := a.checkUserBlockingEnabled("BlockUser"); err != nil {
+		return err
+	}
+
+	if userId == blockedUserId {
+		return model.NewAppError("BlockUser", "app.user_block.block_self.app_error", nil, "user_id="+userId, http.StatusBadRequest)
+	}
+
+	if _, err := a.GetUser(blockedUserId); err != nil {
+		return err
+	}
+
+	preferences := model.Preferences{{
+		UserId:   userId,
+		Category: model.PREFERENCE_CATEGORY_BLOCKED_USER,
+		Name:     blockedUserId,
+		Value:    "true",
+	}}
+	if result := <-a.Srv.Store.Preference().Save(&preferences); result.Err != nil {
+		return result.Err
+	}
+
+	a.PublishPreferencesEvent(model.WEBSOCKET_EVENT_PREFERENCES_CHANGED, userId, preferences)
+
+	return nil
+}
+
+// UnblockUser removes blockedUserId from userId's block list.
+func (a *App) UnblockUser(userId string, blockedUserId string) *model.AppError {
+	if err := a.checkUserBlockingEnabled("UnblockUser"); err != nil {
+		return err
+	}
+
+	if result := <-a.Srv.Store.Preference().Delete(userId, model.PREFERENCE_CATEGORY_BLOCKED_USER, blockedUserId); result.Err != nil {
+		return result.Err
+	}
+
+	preferences := model.Preferences{{UserId: userId, Category: model.PREFERENCE_CATEGORY_BLOCKED_USER, Name: blockedUserId}}
+	a.PublishPreferencesEvent(model.WEBSOCKET_EVENT_PREFERENCES_DELETED, userId, preferences)
+
+	return nil
+}
+
+// GetBlockedUsers returns the users that userId has blocked, with IsBlocked set on each of them.
+func (a *App) GetBlockedUsers(userId string) ([]*model.User, *model.AppError) {
+	if err := a.checkUserBlockingEnabled("GetBlockedUsers"); err != nil {
+		return nil, err
+	}
+
+	blockedUserIds, err := a.getBlockedUserIds(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(blockedUserIds) == 0 {
+		return []*model.User{}, nil
+	}
+
+	ids := make([]string, 0, len(blockedUserIds))
+	for id := range blockedUserIds {
+		ids = append(ids, id)
+	}
+
+	users, err := a.GetUsersByIds(ids, false)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range users {
+		user.IsBlocked = true
+	}
+
+	return users, nil
+}
+
+// SetBlockedFlags sets IsBlocked on each of the users that userId has blocked. It does nothing when blocking is
+// turned off, or if the block list can't be loaded, since the flag only changes how the users are shown.
+func (a *App) SetBlockedFlags(userId string, users []*model.User) {
+	if !*a.Config().ServiceSettings.EnableUserBlocking || len(users) == 0 {
+		return
+	}
+
+	blockedUserIds, err := a.getBlockedUserIds(userId)
+	if err != nil {
+		mlog.Warn("Unable to load blocked users", mlog.String("user_id", userId), mlog.Err(err))
+		return
+	}
+
+	for _, user := range users {
+		user.IsBlocked = blockedUserIds[user.Id]
+	}
+}
+
+func (a *App) checkUserBlockingEnabled(where string) *model.AppError {
+	if !*a.Config().ServiceSettings.EnableUserBlocking {
+		return model.NewAppError(where, "app.user_block.disabled.app_error", nil, "", http.StatusNotImplemented)
+	}
+
+	return nil
+}
+
+func (a *App) getBlockedUserIds(userId string) (map[string]bool, *model.AppError) {
+	result := <-a.Srv.Store.Preference().GetCategory(userId, model.PREFERENCE_CATEGORY_BLOCKED_USER)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	blockedUserIds := make(map[string]bool)
+	for _, preference := range result.Data.(model.Preferences) {
+		if preference.Value == "true" {
+			blockedUserIds[preference.Name] = true
+		}
+	}
+
+	return blockedUserIds, nil
+}
+
+// getUserIdsBlocking returns the ids of the users that have blocked userId, or nil when blocking is turned off.
+func (a *App) getUserIdsBlocking(userId string) map[string]bool {
+	if !*a.Config().ServiceSettings.EnableUserBlocking {
+		return nil
+	}
+
+	result := <-a.Srv.Store.Preference().GetUserIdsByCategoryAndName(model.PREFERENCE_CATEGORY_BLOCKED_USER, userId)
+	if result.Err != nil {
+		mlog.Warn("Unable to load the users blocking a user", mlog.String("user_id", userId), mlog.Err(result.Err))
+		return nil
+	}
+
+	blockers := make(map[string]bool)
+	for _, blockerId := range result.Data.([]string) {
+		blockers[blockerId] = true
+	}
+
+	return blockers
+}
+
+// checkCanCreateDirectChannel returns an error if either user has blocked the other.
+func (a *App) checkCanCreateDirectChannel(userId string, otherUserId string) *model.AppError {
+	if userId == otherUserId {
+		return nil
+	}
+
+	blockers := a.getUserIdsBlocking(userId)
+	if blockers[otherUserId] {
+		return model.NewAppError("CreateDirectChannel", "app.user_block.direct_channel.app_error", nil, "user_id="+otherUserId, http.StatusForbidden)
+	}
+
+	blockers = a.getUserIdsBlocking(otherUserId)
+	if blockers[userId] {
+		return model.NewAppError("CreateDirectChannel", "app.user_block.direct_channel.app_error", nil, "user_id="+otherUserId, http.StatusForbidden)
+	}
+
+	return nil
+}
+
+// removeUsersBlockingSender stops the users that have blocked the sender of a post from being notified about it.
+func (a *App) removeUsersBlockingSender(senderId string, mentionedUserIds map[string]bool, allActivityPushUserIds []string) []string {
+	blockers := a.getUserIdsBlocking(senderId)
+	if len(blockers) == 0 {
+		return allActivityPushUserIds
+	}
+
+	for id := range mentionedUserIds {
+		if blockers[id] {
+			delete(mentionedUserIds, id)
+		}
+	}
+
+	filtered := allActivityPushUserIds[:0]
+	for _, id := range allActivityPushUserIds {
+		if !blockers[id] {
+			filtered = append(filtered, id)
+		}
+	}
+
+	return filtered
+}
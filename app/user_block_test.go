@@ -0,0 +1,137 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+func TestBlockUser(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	t.Run("can't block yourself", func(t *testing.T) {
+		err := th.App.BlockUser(th.BasicUser.Id, th.BasicUser.Id)
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusBadRequest, err.StatusCode)
+	})
+
+	t.Run("can't block a user that doesn't exist", func(t *testing.T) {
+		require.NotNil(t, th.App.BlockUser(th.BasicUser.Id, model.NewId()))
+	})
+
+	t.Run("blocks and unblocks", func(t *testing.T) {
+		require.Nil(t, th.App.BlockUser(th.BasicUser.Id, th.BasicUser2.Id))
+
+		users, err := th.App.GetBlockedUsers(th.BasicUser.Id)
+		require.Nil(t, err)
+		require.Len(t, users, 1)
+		assert.Equal(t, th.BasicUser2.Id, users[0].Id)
+		assert.True(t, users[0].IsBlocked)
+
+		flagged := []*model.User{{Id: th.BasicUser2.Id}, {Id: th.BasicUser.Id}}
+		th.App.SetBlockedFlags(th.BasicUser.Id, flagged)
+		assert.True(t, flagged[0].IsBlocked)
+		assert.False(t, flagged[1].IsBlocked)
+
+		require.Nil(t, th.App.UnblockUser(th.BasicUser.Id, th.BasicUser2.Id))
+
+		users, err = th.App.GetBlockedUsers(th.BasicUser.Id)
+		require.Nil(t, err)
+		assert.Len(t, users, 0)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableUserBlocking = false })
+		defer th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableUserBlocking = true })
+
+		err := th.App.BlockUser(th.BasicUser.Id, th.BasicUser2.Id)
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusNotImplemented, err.StatusCode)
+	})
+}
+
+func TestCreateDirectChannelWithBlockedUser(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	user3 := th.CreateUser()
+	require.Nil(t, th.App.BlockUser(th.BasicUser2.Id, user3.Id))
+
+	_, err := th.App.CreateDirectChannel(th.BasicUser2.Id, user3.Id)
+	require.NotNil(t, err)
+	assert.Equal(t, "app.user_block.direct_channel.app_error", err.Id)
+
+	_, err = th.App.CreateDirectChannel(user3.Id, th.BasicUser2.Id)
+	require.NotNil(t, err, "the blocked user shouldn't be able to start a conversation either")
+
+	_, err = th.App.GetDirectChannel(user3.Id, th.BasicUser2.Id)
+	require.NotNil(t, err)
+
+	// A direct channel that existed before the user was blocked is still returned
+	dm, err := th.App.CreateDirectChannel(th.BasicUser.Id, th.BasicUser2.Id)
+	require.Nil(t, err)
+	require.Nil(t, th.App.BlockUser(th.BasicUser.Id, th.BasicUser2.Id))
+
+	existing, err := th.App.CreateDirectChannel(th.BasicUser2.Id, th.BasicUser.Id)
+	require.Nil(t, err)
+	assert.Equal(t, dm.Id, existing.Id)
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableUserBlocking = false })
+	defer th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableUserBlocking = true })
+
+	_, err = th.App.CreateDirectChannel(user3.Id, th.BasicUser2.Id)
+	require.Nil(t, err)
+}
+
+func TestSendNotificationsFromBlockedUser(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.AddUserToChannel(th.BasicUser2, th.BasicChannel)
+
+	dm, err := th.App.CreateDirectChannel(th.BasicUser.Id, th.BasicUser2.Id)
+	require.Nil(t, err)
+
+	require.Nil(t, th.App.BlockUser(th.BasicUser2.Id, th.BasicUser.Id))
+
+	post, err := th.App.CreatePostMissingChannel(&model.Post{
+		UserId:    th.BasicUser.Id,
+		ChannelId: dm.Id,
+		Message:   "dm message",
+	}, true)
+	require.Nil(t, err)
+
+	mentions, err := th.App.SendNotifications(post, th.BasicTeam, dm, th.BasicUser, nil)
+	require.Nil(t, err)
+	assert.False(t, utils.StringInSlice(th.BasicUser2.Id, mentions))
+
+	post, err = th.App.CreatePostMissingChannel(&model.Post{
+		UserId:    th.BasicUser.Id,
+		ChannelId: th.BasicChannel.Id,
+		Message:   "@" + th.BasicUser2.Username,
+	}, true)
+	require.Nil(t, err)
+
+	mentions, err = th.App.SendNotifications(post, th.BasicTeam, th.BasicChannel, th.BasicUser, nil)
+	require.Nil(t, err)
+	assert.False(t, utils.StringInSlice(th.BasicUser2.Id, mentions))
+
+	member, err := th.App.GetChannelMember(th.BasicChannel.Id, th.BasicUser2.Id)
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), member.MentionCount)
+
+	require.Nil(t, th.App.UnblockUser(th.BasicUser2.Id, th.BasicUser.Id))
+
+	mentions, err = th.App.SendNotifications(post, th.BasicTeam, th.BasicChannel, th.BasicUser, nil)
+	require.Nil(t, err)
+	assert.True(t, utils.StringInSlice(th.BasicUser2.Id, mentions))
+}
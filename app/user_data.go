@@ -0,0 +1,395 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const (
+	USER_DATA_EXPORT_BATCH_SIZE     = 1000
+	USER_ANONYMIZE_POSTS_BATCH_SIZE = 100
+)
+
+type userData struct {
+	profile     *model.User
+	preferences model.Preferences
+	posts       []*model.UserDataExportPost
+	reactions   []*model.Reaction
+	files       []*model.FileInfo
+	sessions    []*model.Session
+	audits      model.Audits
+}
+
+func (d *userData) summary() *model.UserDataExportSummary {
+	return &model.UserDataExportSummary{
+		Preferences: len(d.preferences),
+		Posts:       len(d.posts),
+		Reactions:   len(d.reactions),
+		Files:       len(d.files),
+		Sessions:    len(d.sessions),
+		Audits:      len(d.audits),
+	}
+}
+
+// ExportUserData writes everything stored about the user to w as a zip file with one JSON file per kind of
+// data and the user's uploaded files. If w is nil, nothing is written and only the summary is returned.
+func (a *App) ExportUserData(userId string, w io.Writer) (*model.UserDataExportSummary, *model.AppError) {
+	data, err := a.collectUserData(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if w == nil {
+		return data.summary(), nil
+	}
+
+	if err := a.writeUserDataExport(data, w); err != nil {
+		return nil, err
+	}
+
+	return data.summary(), nil
+}
+
+func (a *App) collectUserData(userId string) (*userData, *model.AppError) {
+	result := <-a.Srv.Store.User().Get(userId)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	profile := result.Data.(*model.User)
+	profile.Sanitize(map[string]bool{})
+
+	data := &userData{profile: profile}
+
+	if result := <-a.Srv.Store.Preference().GetAll(userId); result.Err != nil {
+		return nil, result.Err
+	} else {
+		data.preferences = result.Data.(model.Preferences)
+	}
+
+	posts, err := a.collectUserDataPosts(userId)
+	if err != nil {
+		return nil, err
+	}
+	data.posts = posts
+
+	if result := <-a.Srv.Store.Reaction().GetForUser(userId); result.Err != nil {
+		return nil, result.Err
+	} else {
+		data.reactions = result.Data.([]*model.Reaction)
+	}
+
+	if result := <-a.Srv.Store.FileInfo().GetForUser(userId); result.Err != nil {
+		return nil, result.Err
+	} else {
+		data.files = result.Data.([]*model.FileInfo)
+	}
+
+	if result := <-a.Srv.Store.Session().GetSessions(userId); result.Err != nil {
+		return nil, result.Err
+	} else {
+		data.sessions = result.Data.([]*model.Session)
+		for _, session := range data.sessions {
+			session.Sanitize()
+		}
+	}
+
+	for offset := 0; ; offset += USER_DATA_EXPORT_BATCH_SIZE {
+		result := <-a.Srv.Store.Audit().Get(userId, offset, USER_DATA_EXPORT_BATCH_SIZE)
+		if result.Err != nil {
+			return nil, result.Err
+		}
+
+		audits := result.Data.(model.Audits)
+		data.audits = append(data.audits, audits...)
+		if len(audits) < USER_DATA_EXPORT_BATCH_SIZE {
+			break
+		}
+	}
+
+	return data, nil
+}
+
+func (a *App) collectUserDataPosts(userId string) ([]*model.UserDataExportPost, *model.AppError) {
+	channels := map[string]*model.Channel{}
+	teams := map[string]*model.Team{}
+
+	exportPosts := []*model.UserDataExportPost{}
+	for offset := 0; ; offset += USER_DATA_EXPORT_BATCH_SIZE {
+		result := <-a.Srv.Store.Post().GetPostsByUser(userId, offset, USER_DATA_EXPORT_BATCH_SIZE)
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		posts := result.Data.([]*model.Post)
+
+		for _, post := range posts {
+			exportPost := &model.UserDataExportPost{Post: post}
+
+			channel, ok := channels[post.ChannelId]
+			if !ok {
+				if result := <-a.Srv.Store.Channel().Get(post.ChannelId, true); result.Err == nil {
+					channel = result.Data.(*model.Channel)
+				}
+				channels[post.ChannelId] = channel
+			}
+
+			if channel != nil {
+				exportPost.ChannelName = channel.Name
+				exportPost.ChannelDisplayName = channel.DisplayName
+				exportPost.ChannelType = channel.Type
+
+				team, ok := teams[channel.TeamId]
+				if !ok && channel.TeamId != "" {
+					if result := <-a.Srv.Store.Team().Get(channel.TeamId); result.Err == nil {
+						team = result.Data.(*model.Team)
+					}
+					teams[channel.TeamId] = team
+				}
+
+				if team != nil {
+					exportPost.TeamName = team.Name
+					exportPost.TeamDisplayName = team.DisplayName
+				}
+			}
+
+			exportPosts = append(exportPosts, exportPost)
+		}
+
+		if len(posts) < USER_DATA_EXPORT_BATCH_SIZE {
+			break
+		}
+	}
+
+	return exportPosts, nil
+}
+
+func (a *App) writeUserDataExport(data *userData, w io.Writer) *model.AppError {
+	zipWriter := zip.NewWriter(w)
+
+	writeJson := func(name string, v interface{}) *model.AppError {
+		fileWriter, err := zipWriter.Create(name)
+		if err != nil {
+			return model.NewAppError("ExportUserData", "app.user_data_export.write.app_error", nil, err.Error(), http.StatusInternalServerError)
+		}
+
+		encoder := json.NewEncoder(fileWriter)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(v); err != nil {
+			return model.NewAppError("ExportUserData", "app.user_data_export.write.app_error", nil, err.Error(), http.StatusInternalServerError)
+		}
+
+		return nil
+	}
+
+	for name, v := range map[string]interface{}{
+		model.USER_DATA_EXPORT_PROFILE_FILE:     data.profile,
+		model.USER_DATA_EXPORT_PREFERENCES_FILE: data.preferences,
+		model.USER_DATA_EXPORT_POSTS_FILE:       data.posts,
+		model.USER_DATA_EXPORT_REACTIONS_FILE:   data.reactions,
+		model.USER_DATA_EXPORT_FILES_FILE:       data.files,
+		model.USER_DATA_EXPORT_SESSIONS_FILE:    data.sessions,
+		model.USER_DATA_EXPORT_AUDITS_FILE:      data.audits,
+	} {
+		if err := writeJson(name, v); err != nil {
+			return err
+		}
+	}
+
+	for _, info := range data.files {
+		content, err := a.ReadFile(info.Path)
+		if err != nil {
+			mlog.Warn("Unable to read file for user data export", mlog.String("file_id", info.Id), mlog.String("error", err.Error()))
+			continue
+		}
+
+		fileWriter, zipErr := zipWriter.Create(path.Join(model.USER_DATA_EXPORT_FILES_DIRECTORY, info.Id, info.Name))
+		if zipErr == nil {
+			_, zipErr = fileWriter.Write(content)
+		}
+		if zipErr != nil {
+			return model.NewAppError("ExportUserData", "app.user_data_export.write.app_error", nil, zipErr.Error(), http.StatusInternalServerError)
+		}
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return model.NewAppError("ExportUserData", "app.user_data_export.write.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+var mentionPattern = regexp.MustCompile(`(?i)(^|[^a-z0-9.\-_@])@([a-z0-9.\-_]+)`)
+
+// replaceUsernameMentions replaces @mentions of oldUsername in message, including ones followed by
+// punctuation, and returns the new message along with the number of mentions replaced.
+func replaceUsernameMentions(message string, oldUsername string, newUsername string) (string, int) {
+	count := 0
+
+	replaced := mentionPattern.ReplaceAllStringFunc(message, func(match string) string {
+		at := strings.Index(match, "@")
+		name := match[at+1:]
+
+		for mentioned := name; len(mentioned) > 0; mentioned = mentioned[:len(mentioned)-1] {
+			if strings.ToLower(mentioned) == oldUsername {
+				count++
+				return match[:at+1] + newUsername + name[len(mentioned):]
+			}
+
+			if !strings.ContainsAny(mentioned[len(mentioned)-1:], ".-_") {
+				break
+			}
+		}
+
+		return match
+	})
+
+	return replaced, count
+}
+
+// AnonymizeUser replaces the user's username, email and name with opaque values and removes their
+// authentication data, keeping the account itself so that everything referencing it stays intact. If
+// rewriteMentions is set, @mentions of the old username in posts are changed to the new one.
+func (a *App) AnonymizeUser(userId string, rewriteMentions bool, dryRun bool) (*model.UserAnonymizeResult, *model.AppError) {
+	result := <-a.Srv.Store.User().Get(userId)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	user := result.Data.(*model.User)
+
+	id := model.NewId()
+	anonymizeResult := &model.UserAnonymizeResult{
+		DryRun:      dryRun,
+		OldUsername: user.Username,
+		Username:    model.USER_ANONYMIZED_USERNAME_PREFIX + id,
+		Email:       id + "@" + model.USER_ANONYMIZED_EMAIL_DOMAIN,
+	}
+
+	if rewriteMentions {
+		rewritten, err := a.rewriteUsernameMentions(user.Username, anonymizeResult.Username, dryRun)
+		if err != nil {
+			return nil, err
+		}
+		anonymizeResult.PostsRewritten = rewritten
+	}
+
+	if dryRun {
+		return anonymizeResult, nil
+	}
+
+	oldUsername := user.Username
+	user.Username = anonymizeResult.Username
+	user.Email = anonymizeResult.Email
+	user.Nickname = ""
+	user.FirstName = ""
+	user.LastName = ""
+	user.Position = ""
+	user.Props = model.StringMap{}
+	user.Timezone = model.DefaultUserTimezone()
+	user.MakeNonNil()
+	// Only keep the username keys, which are replaced with the new username when updating.
+	user.NotifyProps["mention_keys"] = oldUsername
+
+	if result := <-a.Srv.Store.User().Update(user, true); result.Err != nil {
+		return nil, result.Err
+	}
+
+	// The old usernames would otherwise still lead to the user's direct channels
+	if result := <-a.Srv.Store.User().DeleteUsernameHistory(userId); result.Err != nil {
+		return nil, result.Err
+	}
+
+	// Updating the user keeps their auth data, which would otherwise still identify them with their SSO provider
+	if result := <-a.Srv.Store.User().UpdateAuthData(userId, "", nil, "", true); result.Err != nil {
+		return nil, result.Err
+	}
+
+	if result := <-a.Srv.Store.User().UpdatePassword(userId, model.HashPassword(model.NewId())); result.Err != nil {
+		return nil, result.Err
+	}
+
+	if result := <-a.Srv.Store.User().UpdateMfaActive(userId, false); result.Err != nil {
+		return nil, result.Err
+	}
+
+	if result := <-a.Srv.Store.User().UpdateMfaSecret(userId, ""); result.Err != nil {
+		return nil, result.Err
+	}
+
+	if len(*a.Config().FileSettings.DriverName) != 0 {
+		// The image may not exist if it has never been requested.
+		if err := a.RemoveFile("users/" + userId + "/profile.png"); err != nil {
+			mlog.Debug("Unable to remove profile image of anonymized user", mlog.String("user_id", userId), mlog.String("error", err.Error()))
+		}
+		if err := a.RemoveDirectory("users/" + userId + "/initials"); err != nil {
+			mlog.Debug("Unable to remove initials profile image of anonymized user", mlog.String("user_id", userId), mlog.String("error", err.Error()))
+		}
+
+		if result := <-a.Srv.Store.User().UpdateLastPictureUpdate(userId); result.Err != nil {
+			return nil, result.Err
+		}
+	}
+
+	if err := a.RevokeAllSessions(userId); err != nil {
+		return nil, err
+	}
+
+	a.InvalidateCacheForUser(userId)
+
+	if ruser, err := a.GetUser(userId); err == nil {
+		a.sendUpdatedUserEvent(*ruser)
+	}
+
+	return anonymizeResult, nil
+}
+
+func (a *App) rewriteUsernameMentions(oldUsername string, newUsername string, dryRun bool) (int, *model.AppError) {
+	rewritten := 0
+
+	afterId := ""
+	for {
+		result := <-a.Srv.Store.Post().GetPostsWithMessageContaining("@"+oldUsername, afterId, USER_ANONYMIZE_POSTS_BATCH_SIZE)
+		if result.Err != nil {
+			return rewritten, result.Err
+		}
+		posts := result.Data.([]*model.Post)
+
+		for _, post := range posts {
+			afterId = post.Id
+
+			message, count := replaceUsernameMentions(post.Message, oldUsername, newUsername)
+			if count == 0 {
+				continue
+			}
+			rewritten++
+
+			if dryRun {
+				continue
+			}
+
+			post.Message = message
+			if result := <-a.Srv.Store.Post().Overwrite(post); result.Err != nil {
+				return rewritten, result.Err
+			}
+		}
+
+		if len(posts) < USER_ANONYMIZE_POSTS_BATCH_SIZE {
+			break
+		}
+	}
+
+	if rewritten > 0 && !dryRun {
+		a.Srv.Store.Post().ClearCaches()
+	}
+
+	return rewritten, nil
+}
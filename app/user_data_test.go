@@ -0,0 +1,210 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func TestReplaceUsernameMentions(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Message  string
+		Expected string
+		Count    int
+	}{
+		"no mention":           {"hello there", "hello there", 0},
+		"mention":              {"hello @alice", "hello @anon", 1},
+		"start of message":     {"@alice hello", "@anon hello", 1},
+		"upper case":           {"hello @Alice", "hello @anon", 1},
+		"trailing punctuation": {"hello @alice. and @alice_, @alice-", "hello @anon. and @anon_, @anon-", 3},
+		"longer username":      {"hello @alicevonb and @alice.b", "hello @alicevonb and @alice.b", 0},
+		"email address":        {"mail bob@alice.com", "mail bob@alice.com", 0},
+		"several mentions":     {"@alice, @bob and @alice", "@anon, @bob and @anon", 2},
+	} {
+		t.Run(name, func(t *testing.T) {
+			message, count := replaceUsernameMentions(tc.Message, "alice", "anon")
+			assert.Equal(t, tc.Expected, message)
+			assert.Equal(t, tc.Count, count)
+		})
+	}
+}
+
+func readUserDataExport(t *testing.T, data []byte) map[string][]byte {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.Nil(t, err)
+
+	files := map[string][]byte{}
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		require.Nil(t, err)
+		content, err := ioutil.ReadAll(rc)
+		rc.Close()
+		require.Nil(t, err)
+		files[file.Name] = content
+	}
+
+	return files
+}
+
+func TestExportUserData(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	user := th.BasicUser
+
+	post := th.CreatePost(th.BasicChannel)
+	_, err := th.App.SaveReactionForPost(&model.Reaction{UserId: user.Id, PostId: post.Id, EmojiName: "smile"})
+	require.Nil(t, err)
+
+	require.Nil(t, th.App.UpdatePreferences(user.Id, model.Preferences{{UserId: user.Id, Category: model.PREFERENCE_CATEGORY_DISPLAY_SETTINGS, Name: "use_military_time", Value: "true"}}))
+
+	info, err := th.App.DoUploadFile(time.Now(), th.BasicTeam.Id, th.BasicChannel.Id, user.Id, "notes.txt", []byte("my notes"))
+	require.Nil(t, err)
+	defer func() {
+		<-th.App.Srv.Store.FileInfo().PermanentDelete(info.Id)
+		th.App.RemoveFile(info.Path)
+	}()
+
+	_, err = th.App.CreateSession(&model.Session{UserId: user.Id})
+	require.Nil(t, err)
+
+	summary, err := th.App.ExportUserData(user.Id, nil)
+	require.Nil(t, err)
+	assert.Equal(t, 1, summary.Posts)
+	assert.Equal(t, 1, summary.Reactions)
+	assert.Equal(t, 1, summary.Files)
+	assert.True(t, summary.Preferences >= 1)
+	assert.True(t, summary.Sessions >= 1)
+
+	var buf bytes.Buffer
+	exported, err := th.App.ExportUserData(user.Id, &buf)
+	require.Nil(t, err)
+	assert.Equal(t, summary, exported)
+
+	files := readUserDataExport(t, buf.Bytes())
+	for _, name := range []string{
+		model.USER_DATA_EXPORT_PROFILE_FILE,
+		model.USER_DATA_EXPORT_PREFERENCES_FILE,
+		model.USER_DATA_EXPORT_POSTS_FILE,
+		model.USER_DATA_EXPORT_REACTIONS_FILE,
+		model.USER_DATA_EXPORT_FILES_FILE,
+		model.USER_DATA_EXPORT_SESSIONS_FILE,
+		model.USER_DATA_EXPORT_AUDITS_FILE,
+	} {
+		assert.Contains(t, files, name)
+	}
+
+	var profile model.User
+	require.Nil(t, json.Unmarshal(files[model.USER_DATA_EXPORT_PROFILE_FILE], &profile))
+	assert.Equal(t, user.Id, profile.Id)
+	assert.Equal(t, user.Email, profile.Email)
+	assert.Empty(t, profile.Password)
+
+	var posts []*model.UserDataExportPost
+	require.Nil(t, json.Unmarshal(files[model.USER_DATA_EXPORT_POSTS_FILE], &posts))
+	require.Len(t, posts, 1)
+	assert.Equal(t, post.Message, posts[0].Post.Message)
+	assert.Equal(t, th.BasicChannel.Name, posts[0].ChannelName)
+	assert.Equal(t, th.BasicTeam.Name, posts[0].TeamName)
+
+	var sessions []*model.Session
+	require.Nil(t, json.Unmarshal(files[model.USER_DATA_EXPORT_SESSIONS_FILE], &sessions))
+	for _, session := range sessions {
+		assert.Empty(t, session.Token, "session tokens shouldn't be exported")
+	}
+
+	assert.Equal(t, []byte("my notes"), files[path.Join(model.USER_DATA_EXPORT_FILES_DIRECTORY, info.Id, info.Name)])
+}
+
+func TestAnonymizeUser(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	user := th.BasicUser
+	oldUsername := user.Username
+
+	own := th.CreatePost(th.BasicChannel)
+	mention, err := th.App.CreatePost(&model.Post{
+		UserId:    th.BasicUser2.Id,
+		ChannelId: th.BasicChannel.Id,
+		Message:   "thanks @" + oldUsername + "!",
+	}, th.BasicChannel, false)
+	require.Nil(t, err)
+
+	result, err := th.App.AnonymizeUser(user.Id, true, true)
+	require.Nil(t, err)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, oldUsername, result.OldUsername)
+	assert.Equal(t, 1, result.PostsRewritten)
+
+	ruser, err := th.App.GetUser(user.Id)
+	require.Nil(t, err)
+	assert.Equal(t, oldUsername, ruser.Username, "a dry run shouldn't change anything")
+
+	result, err = th.App.AnonymizeUser(user.Id, true, false)
+	require.Nil(t, err)
+	assert.False(t, result.DryRun)
+	assert.Equal(t, 1, result.PostsRewritten)
+	assert.True(t, strings.HasPrefix(result.Username, model.USER_ANONYMIZED_USERNAME_PREFIX))
+
+	ruser, err = th.App.GetUser(user.Id)
+	require.Nil(t, err)
+	assert.Equal(t, result.Username, ruser.Username)
+	assert.Equal(t, result.Email, ruser.Email)
+	assert.Empty(t, ruser.FirstName)
+	assert.Empty(t, ruser.LastName)
+	assert.Empty(t, ruser.Nickname)
+	assert.Equal(t, "", ruser.AuthService)
+	assert.NotContains(t, ruser.NotifyProps["mention_keys"], oldUsername)
+
+	_, err = th.App.GetUserByUsername(oldUsername)
+	assert.NotNil(t, err)
+
+	rpost, err := th.App.GetSinglePost(own.Id)
+	require.Nil(t, err, "the user's posts should still be readable")
+	assert.Equal(t, own.Message, rpost.Message)
+	assert.Equal(t, user.Id, rpost.UserId)
+
+	rpost, err = th.App.GetSinglePost(mention.Id)
+	require.Nil(t, err)
+	assert.Equal(t, "thanks @"+result.Username+"!", rpost.Message)
+}
+
+func TestAnonymizeSsoUser(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	authData := model.NewId()
+	user := store.Must(th.App.Srv.Store.User().Save(&model.User{
+		Email:       th.MakeEmail(),
+		Username:    "sso" + model.NewId(),
+		AuthService: model.USER_AUTH_SERVICE_GITLAB,
+		AuthData:    &authData,
+	})).(*model.User)
+
+	_, err := th.App.AnonymizeUser(user.Id, false, false)
+	require.Nil(t, err)
+
+	result := <-th.App.Srv.Store.User().Get(user.Id)
+	require.Nil(t, result.Err)
+	ruser := result.Data.(*model.User)
+	assert.Equal(t, "", ruser.AuthService)
+	assert.True(t, ruser.AuthData == nil || *ruser.AuthData == "", "the user's SSO identifier should be cleared")
+
+	result = <-th.App.Srv.Store.User().GetByAuth(&authData, model.USER_AUTH_SERVICE_GITLAB)
+	assert.NotNil(t, result.Err, "signing in with the SSO provider shouldn't find the anonymized user")
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// ScheduleUserDeactivation sets the time at which the user is deactivated by the user deactivation job.
+func (a *App) ScheduleUserDeactivation(userId string, deactivateAt int64) (*model.User, *model.AppError) {
+	user, err := a.GetUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsSSOUser() {
+		return nil, model.NewAppError("ScheduleUserDeactivation", "api.user.update_active.no_deactivate_sso.app_error", nil, "userId="+user.Id, http.StatusBadRequest)
+	}
+
+	if user.DeleteAt != 0 {
+		return nil, model.NewAppError("ScheduleUserDeactivation", "app.user.schedule_deactivation.inactive.app_error", nil, "userId="+user.Id, http.StatusBadRequest)
+	}
+
+	if deactivateAt <= model.GetMillis() {
+		return nil, model.NewAppError("ScheduleUserDeactivation", "app.user.schedule_deactivation.past.app_error", nil, "userId="+user.Id, http.StatusBadRequest)
+	}
+
+	return a.setUserDeactivateAt(user, deactivateAt)
+}
+
+// CancelUserDeactivation removes the scheduled deactivation of the user, if any.
+func (a *App) CancelUserDeactivation(userId string) (*model.User, *model.AppError) {
+	user, err := a.GetUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.DeactivateAt == 0 {
+		return user, nil
+	}
+
+	return a.setUserDeactivateAt(user, 0)
+}
+
+func (a *App) setUserDeactivateAt(user *model.User, deactivateAt int64) (*model.User, *model.AppError) {
+	if result := <-a.Srv.Store.User().UpdateDeactivateAt(user.Id, deactivateAt); result.Err != nil {
+		return nil, result.Err
+	}
+
+	a.InvalidateCacheForUser(user.Id)
+
+	user.DeactivateAt = deactivateAt
+	a.sendUpdatedUserEvent(*user.DeepCopy())
+
+	return user, nil
+}
+
+func (a *App) GetUsersDueForDeactivation(now int64) ([]*model.User, *model.AppError) {
+	result := <-a.Srv.Store.User().GetDueForDeactivation(now)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	return result.Data.([]*model.User), nil
+}
+
+// DeactivateScheduledUsers deactivates every user whose scheduled deactivation time is at or before now, and
+// returns how many were deactivated.
+func (a *App) DeactivateScheduledUsers(now int64) (int, *model.AppError) {
+	users, err := a.GetUsersDueForDeactivation(now)
+	if err != nil {
+		return 0, err
+	}
+
+	deactivated := 0
+	for _, user := range users {
+		if _, err := a.UpdateActive(user, false); err != nil {
+			mlog.Error("Unable to deactivate scheduled user", mlog.String("user_id", user.Id), mlog.String("error", err.Error()))
+			continue
+		}
+		deactivated++
+	}
+
+	if deactivated < len(users) {
+		return deactivated, model.NewAppError("DeactivateScheduledUsers", "app.user.deactivate_scheduled_users.app_error", nil, "", http.StatusInternalServerError)
+	}
+
+	return deactivated, nil
+}
+
+// archiveDirectChannelsForUser archives the direct channels of a user who's been deactivated so that they're hidden
+// from the other users until it's reactivated.
+func (a *App) archiveDirectChannelsForUser(userId string) *model.AppError {
+	result := <-a.Srv.Store.Channel().ArchiveDirectChannelsForUser(userId, model.GetMillis())
+	if result.Err != nil {
+		return result.Err
+	}
+
+	for _, channel := range result.Data.([]*model.Channel) {
+		a.InvalidateCacheForChannel(channel)
+
+		message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_CHANNEL_DELETED, "", channel.Id, "", nil)
+		message.Add("channel_id", channel.Id)
+		a.Publish(message)
+	}
+
+	return nil
+}
+
+// restoreDirectChannelsForUser restores the direct channels that were archived when a user was deactivated, other
+// than those with users who are still deactivated.
+func (a *App) restoreDirectChannelsForUser(userId string) *model.AppError {
+	result := <-a.Srv.Store.Channel().RestoreDirectChannelsForUser(userId, model.GetMillis())
+	if result.Err != nil {
+		return result.Err
+	}
+
+	for _, channel := range result.Data.([]*model.Channel) {
+		a.InvalidateCacheForChannel(channel)
+
+		message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_CHANNEL_UPDATED, "", channel.Id, "", nil)
+		message.Add("changes", model.StringInterfaceToJson(map[string]interface{}{"delete_at": 0, "update_at": channel.UpdateAt}))
+		a.Publish(message)
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestDeactivateScheduledUsers(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	user := th.CreateUser()
+	session, err := th.App.CreateSession(&model.Session{UserId: user.Id})
+	require.Nil(t, err)
+
+	deactivateAt := model.GetMillis() + 60*60*1000
+
+	_, err = th.App.ScheduleUserDeactivation(user.Id, model.GetMillis()-1000)
+	require.NotNil(t, err)
+	assert.Equal(t, "app.user.schedule_deactivation.past.app_error", err.Id)
+
+	scheduled, err := th.App.ScheduleUserDeactivation(user.Id, deactivateAt)
+	require.Nil(t, err)
+	assert.Equal(t, deactivateAt, scheduled.DeactivateAt)
+
+	deactivated, err := th.App.DeactivateScheduledUsers(deactivateAt - 1)
+	require.Nil(t, err)
+	assert.Equal(t, 0, deactivated)
+
+	_, err = th.App.GetSession(session.Token)
+	require.Nil(t, err, "the session should be valid until the scheduled time")
+
+	deactivated, err = th.App.DeactivateScheduledUsers(deactivateAt)
+	require.Nil(t, err)
+	assert.Equal(t, 1, deactivated)
+
+	_, err = th.App.GetSession(session.Token)
+	assert.NotNil(t, err, "the session should be revoked at the scheduled time")
+
+	ruser, err := th.App.GetUser(user.Id)
+	require.Nil(t, err)
+	assert.NotEqual(t, int64(0), ruser.DeleteAt)
+	assert.Equal(t, int64(0), ruser.DeactivateAt)
+
+	_, err = th.App.ScheduleUserDeactivation(user.Id, deactivateAt)
+	require.NotNil(t, err)
+	assert.Equal(t, "app.user.schedule_deactivation.inactive.app_error", err.Id)
+}
+
+func TestCancelUserDeactivation(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	deactivateAt := model.GetMillis() + 60*60*1000
+
+	_, err := th.App.ScheduleUserDeactivation(th.BasicUser.Id, deactivateAt)
+	require.Nil(t, err)
+
+	user, err := th.App.CancelUserDeactivation(th.BasicUser.Id)
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), user.DeactivateAt)
+
+	deactivated, err := th.App.DeactivateScheduledUsers(deactivateAt)
+	require.Nil(t, err)
+	assert.Equal(t, 0, deactivated)
+
+	user, err = th.App.GetUser(th.BasicUser.Id)
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), user.DeleteAt)
+
+	t.Run("immediate deactivation clears the schedule", func(t *testing.T) {
+		_, err := th.App.ScheduleUserDeactivation(th.BasicUser2.Id, deactivateAt)
+		require.Nil(t, err)
+
+		user, err := th.App.GetUser(th.BasicUser2.Id)
+		require.Nil(t, err)
+		_, err = th.App.UpdateActive(user, false)
+		require.Nil(t, err)
+		_, err = th.App.UpdateActive(user, true)
+		require.Nil(t, err)
+
+		user, err = th.App.GetUser(th.BasicUser2.Id)
+		require.Nil(t, err)
+		assert.Equal(t, int64(0), user.DeleteAt)
+		assert.Equal(t, int64(0), user.DeactivateAt)
+	})
+}
+
+func TestArchiveDirectChannelsOnDeactivation(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	dm := th.CreateDmChannel(th.BasicUser2)
+
+	t.Run("not archived by default", func(t *testing.T) {
+		_, err := th.App.UpdateActive(th.BasicUser2, false)
+		require.Nil(t, err)
+
+		channel, err := th.App.GetChannel(dm.Id)
+		require.Nil(t, err)
+		assert.Equal(t, int64(0), channel.DeleteAt)
+
+		_, err = th.App.UpdateActive(th.BasicUser2, true)
+		require.Nil(t, err)
+	})
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.DeactivationSettings.ArchiveDirectChannels = true })
+
+	t.Run("archived and restored", func(t *testing.T) {
+		_, err := th.App.UpdateActive(th.BasicUser2, false)
+		require.Nil(t, err)
+
+		channel, err := th.App.GetChannel(dm.Id)
+		require.Nil(t, err)
+		assert.NotEqual(t, int64(0), channel.DeleteAt)
+
+		_, err = th.App.UpdateActive(th.BasicUser2, true)
+		require.Nil(t, err)
+
+		channel, err = th.App.GetChannel(dm.Id)
+		require.Nil(t, err)
+		assert.Equal(t, int64(0), channel.DeleteAt, "direct channels should be restored when the user is reactivated")
+	})
+
+	t.Run("restored once the setting is turned off", func(t *testing.T) {
+		_, err := th.App.UpdateActive(th.BasicUser2, false)
+		require.Nil(t, err)
+
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.DeactivationSettings.ArchiveDirectChannels = false })
+
+		_, err = th.App.UpdateActive(th.BasicUser2, true)
+		require.Nil(t, err)
+
+		channel, err := th.App.GetChannel(dm.Id)
+		require.Nil(t, err)
+		assert.Equal(t, int64(0), channel.DeleteAt)
+	})
+}
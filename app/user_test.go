@@ -9,11 +9,13 @@ import (
 	"image"
 	"image/color"
 	"math/rand"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/mattermost/mattermost-server/einterfaces"
 	"github.com/mattermost/mattermost-server/model"
@@ -133,6 +135,92 @@ func TestCreateProfileImage(t *testing.T) {
 	}
 }
 
+func TestCreateInitialsProfileImage(t *testing.T) {
+	userId := "eo1zkdr96pdj98pjmq8zy35wba"
+
+	b, err := CreateInitialsProfileImage("CH", userId, "luximbi.ttf")
+	require.Nil(t, err)
+
+	again, err := CreateInitialsProfileImage("CH", userId, "luximbi.ttf")
+	require.Nil(t, err)
+	assert.Equal(t, b, again, "the same image should be made every time")
+
+	img, _, decodeErr := image.Decode(bytes.NewReader(b))
+	require.Nil(t, decodeErr)
+	assert.Equal(t, color.RGBA{116, 49, 196, 255}, img.At(1, 1), "the color should be the same as for the first letter of the username")
+
+	other, err := CreateInitialsProfileImage("CD", userId, "luximbi.ttf")
+	require.Nil(t, err)
+	assert.NotEqual(t, b, other)
+}
+
+func TestGetProfileImageWithInitials(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.TeamSettings.TeammateNameDisplay = model.SHOW_FULLNAME })
+
+	user := th.CreateUser()
+	defer th.App.RemoveDirectory("users/" + user.Id)
+	user.FirstName = "John"
+	user.LastName = "Doe"
+	user, err := th.App.UpdateUser(user, false)
+	require.Nil(t, err)
+
+	img, readFailed, err := th.App.GetProfileImage(user)
+	require.Nil(t, err)
+	assert.False(t, readFailed)
+	expected, err := CreateInitialsProfileImage("JD", user.Id, th.App.Config().FileSettings.InitialFont)
+	require.Nil(t, err)
+	assert.Equal(t, expected, img)
+
+	path := "users/" + user.Id + "/initials/" + th.App.getProfileImageInitialsKey(user) + ".png"
+	cached, err := th.App.ReadFile(path)
+	require.Nil(t, err, "the image should be kept in the file store")
+	assert.Equal(t, img, cached)
+
+	etag := th.App.GetProfileImageEtag(user)
+	again, _, err := th.App.GetProfileImage(user)
+	require.Nil(t, err)
+	assert.Equal(t, img, again)
+	assert.Equal(t, etag, th.App.GetProfileImageEtag(user))
+
+	t.Run("renamed", func(t *testing.T) {
+		user.FirstName = "Jane"
+		user.LastName = "Smith"
+		user, err := th.App.UpdateUser(user, false)
+		require.Nil(t, err)
+
+		assert.NotEqual(t, etag, th.App.GetProfileImageEtag(user), "the etag should change with the initials")
+
+		renamed, _, err := th.App.GetProfileImage(user)
+		require.Nil(t, err)
+		expected, err := CreateInitialsProfileImage("JS", user.Id, th.App.Config().FileSettings.InitialFont)
+		require.Nil(t, err)
+		assert.Equal(t, expected, renamed)
+
+		_, err = th.App.ReadFile(path)
+		assert.NotNil(t, err, "the image for the old initials should be removed")
+	})
+
+	t.Run("uploaded image", func(t *testing.T) {
+		user := &model.User{Id: user.Id, Username: user.Username, LastPictureUpdate: model.GetMillis()}
+		assert.Equal(t, strconv.FormatInt(user.LastPictureUpdate, 10), th.App.GetProfileImageEtag(user))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.FileSettings.EnableProfileImageInitials = false })
+		defer th.App.UpdateConfig(func(cfg *model.Config) { *cfg.FileSettings.EnableProfileImageInitials = true })
+
+		img, _, err := th.App.GetProfileImage(user)
+		require.Nil(t, err)
+		expected, err := CreateProfileImage(user.Username, user.Id, th.App.Config().FileSettings.InitialFont)
+		require.Nil(t, err)
+		assert.Equal(t, expected, img, "the first letter of the username should be used")
+		assert.Equal(t, "0", th.App.GetProfileImageEtag(user))
+	})
+}
+
 func TestUpdateUserToRestrictedDomain(t *testing.T) {
 	th := Setup()
 	defer th.TearDown()
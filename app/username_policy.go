@@ -0,0 +1,158 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+const USERNAME_POLICY_REPORT_BATCH_SIZE = 1000
+
+// checkUsernamePolicy returns an error if a user being created or updated breaks the username policy. When updating
+// a user, prev is the user as they were before, so that only the username and names that are being changed are
+// checked.
+func (a *App) checkUsernamePolicy(user *model.User, prev *model.User) *model.AppError {
+	settings := a.Config().UsernamePolicySettings
+	if !*settings.Enable || isExemptFromUsernamePolicy(&settings, user) {
+		return nil
+	}
+
+	if prev == nil || prev.Username != user.Username {
+		switch usernamePolicyViolation(&settings, user.Username) {
+		case model.USERNAME_POLICY_VIOLATION_RESERVED:
+			return model.NewAppError("checkUsernamePolicy", "app.user.username_policy.reserved.app_error", map[string]interface{}{"Username": user.Username}, "", http.StatusBadRequest)
+		case model.USERNAME_POLICY_VIOLATION_PATTERN:
+			return model.NewAppError("checkUsernamePolicy", "app.user.username_policy.pattern.app_error", map[string]interface{}{"Username": user.Username}, "", http.StatusBadRequest)
+		}
+	}
+
+	if *settings.BlockNameImpersonation && (prev == nil || prev.FirstName != user.FirstName || prev.LastName != user.LastName || prev.Nickname != user.Nickname) {
+		names := claimedNames(user)
+		if len(names) == 0 {
+			return nil
+		}
+
+		result := <-a.Srv.Store.User().GetByFullNames(names)
+		if result.Err != nil {
+			return result.Err
+		}
+
+		for _, other := range result.Data.([]*model.User) {
+			if other.Id != user.Id {
+				return model.NewAppError("checkUsernamePolicy", "app.user.username_policy.impersonation.app_error", nil, "user_id="+user.Id+", impersonated_user_id="+other.Id, http.StatusBadRequest)
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetUsernamePolicyViolations returns the active users that break the username policy as it's currently configured,
+// even if it isn't enabled, so that admins can see who it would affect before turning it on.
+func (a *App) GetUsernamePolicyViolations() ([]*model.UsernamePolicyViolation, *model.AppError) {
+	settings := a.Config().UsernamePolicySettings
+
+	var users []*model.User
+	for offset := 0; ; offset += USERNAME_POLICY_REPORT_BATCH_SIZE {
+		result := <-a.Srv.Store.User().GetAllProfiles(offset, USERNAME_POLICY_REPORT_BATCH_SIZE)
+		if result.Err != nil {
+			return nil, result.Err
+		}
+
+		batch := result.Data.([]*model.User)
+		for _, user := range batch {
+			if user.DeleteAt == 0 {
+				users = append(users, user)
+			}
+		}
+
+		if len(batch) < USERNAME_POLICY_REPORT_BATCH_SIZE {
+			break
+		}
+	}
+
+	usersByFullName := make(map[string][]string)
+	for _, user := range users {
+		if fullName := strings.ToLower(strings.TrimSpace(user.GetFullName())); fullName != "" {
+			usersByFullName[fullName] = append(usersByFullName[fullName], user.Id)
+		}
+	}
+
+	violations := []*model.UsernamePolicyViolation{}
+	for _, user := range users {
+		if isExemptFromUsernamePolicy(&settings, user) {
+			continue
+		}
+
+		if violation := usernamePolicyViolation(&settings, user.Username); violation != "" {
+			violations = append(violations, &model.UsernamePolicyViolation{UserId: user.Id, Username: user.Username, Violation: violation})
+		}
+
+		if !*settings.BlockNameImpersonation {
+			continue
+		}
+
+		var impersonated []string
+		for _, name := range claimedNames(user) {
+			for _, userId := range usersByFullName[name] {
+				if userId != user.Id && !utils.StringInSlice(userId, impersonated) {
+					impersonated = append(impersonated, userId)
+				}
+			}
+		}
+
+		if len(impersonated) > 0 {
+			violations = append(violations, &model.UsernamePolicyViolation{
+				UserId:              user.Id,
+				Username:            user.Username,
+				Violation:           model.USERNAME_POLICY_VIOLATION_IMPERSONATION,
+				ImpersonatedUserIds: impersonated,
+			})
+		}
+	}
+
+	return violations, nil
+}
+
+// isExemptFromUsernamePolicy returns whether the username policy doesn't apply to a user. Bots are always exempt,
+// and AD/LDAP users are exempt if their usernames are managed by the directory.
+func isExemptFromUsernamePolicy(settings *model.UsernamePolicySettings, user *model.User) bool {
+	return user.IsBot || (*settings.ExemptLdapUsers && user.IsLDAPUser())
+}
+
+// usernamePolicyViolation returns which part of the username policy a username breaks, or an empty string if it
+// doesn't break any. The pattern has to match the whole username.
+func usernamePolicyViolation(settings *model.UsernamePolicySettings, username string) string {
+	for _, reserved := range strings.Fields(strings.ToLower(*settings.ReservedUsernames)) {
+		if username == reserved {
+			return model.USERNAME_POLICY_VIOLATION_RESERVED
+		}
+	}
+
+	if *settings.UsernamePattern != "" {
+		if pattern, err := regexp.Compile("^(?:" + *settings.UsernamePattern + ")$"); err == nil && !pattern.MatchString(username) {
+			return model.USERNAME_POLICY_VIOLATION_PATTERN
+		}
+	}
+
+	return ""
+}
+
+// claimedNames returns the names that a user is shown by, other than their username, in lower case.
+func claimedNames(user *model.User) []string {
+	var names []string
+	for _, name := range []string{user.GetFullName(), user.Nickname} {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" && !utils.StringInSlice(name, names) {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestUsernamePolicy(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.UsernamePolicySettings.Enable = true
+		*cfg.UsernamePolicySettings.UsernamePattern = `[a-z]+\.[a-z0-9]+`
+		*cfg.UsernamePolicySettings.ReservedUsernames = "ceo.office Security.Team"
+		*cfg.UsernamePolicySettings.BlockNameImpersonation = true
+	})
+
+	newUser := func(username string) *model.User {
+		return &model.User{
+			Email:    "success+" + model.NewId() + "@simulator.amazonses.com",
+			Username: username,
+			Password: "Password1",
+		}
+	}
+
+	t.Run("pattern", func(t *testing.T) {
+		_, err := th.App.CreateUser(newUser("jdoe" + model.NewRandomString(6)))
+		require.NotNil(t, err)
+		assert.Equal(t, "app.user.username_policy.pattern.app_error", err.Id)
+
+		user, err := th.App.CreateUser(newUser("jane.doe" + model.NewRandomString(6)))
+		require.Nil(t, err)
+
+		user.Username = "jane_doe" + model.NewRandomString(6)
+		_, err = th.App.UpdateUser(user, false)
+		require.NotNil(t, err, "username changes have to match too")
+		assert.Equal(t, "app.user.username_policy.pattern.app_error", err.Id)
+	})
+
+	t.Run("reserved names", func(t *testing.T) {
+		_, err := th.App.CreateUser(newUser("ceo.office"))
+		require.NotNil(t, err)
+		assert.Equal(t, "app.user.username_policy.reserved.app_error", err.Id)
+
+		_, err = th.App.CreateUser(newUser("security.team"))
+		require.NotNil(t, err, "reserved names aren't case sensitive")
+		assert.Equal(t, "app.user.username_policy.reserved.app_error", err.Id)
+	})
+
+	t.Run("existing usernames are only checked when they change", func(t *testing.T) {
+		user, err := th.App.GetUser(th.BasicUser.Id)
+		require.Nil(t, err)
+
+		user.Position = "engineer"
+		_, err = th.App.UpdateUser(user, false)
+		require.Nil(t, err)
+	})
+
+	t.Run("impersonation", func(t *testing.T) {
+		lastName := "ceo" + model.NewRandomString(6)
+		ceo := newUser("ceo." + lastName)
+		ceo.FirstName = "Alex"
+		ceo.LastName = lastName
+		ceo, err := th.App.CreateUser(ceo)
+		require.Nil(t, err, "a user can use their own name")
+
+		impostor := newUser("alex." + model.NewRandomString(6))
+		impostor.FirstName = "ALEX"
+		impostor.LastName = lastName
+		_, err = th.App.CreateUser(impostor)
+		require.NotNil(t, err)
+		assert.Equal(t, "app.user.username_policy.impersonation.app_error", err.Id)
+
+		impostor.FirstName = ""
+		impostor.LastName = ""
+		impostor, err = th.App.CreateUser(impostor)
+		require.Nil(t, err)
+
+		impostor.Nickname = " alex " + lastName
+		_, err = th.App.UpdateUser(impostor, false)
+		require.NotNil(t, err, "nicknames can't impersonate either")
+		assert.Equal(t, "app.user.username_policy.impersonation.app_error", err.Id)
+
+		ceo.Position = "ceo"
+		_, err = th.App.UpdateUser(ceo, false)
+		require.Nil(t, err)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.UsernamePolicySettings.Enable = false })
+		defer th.App.UpdateConfig(func(cfg *model.Config) { *cfg.UsernamePolicySettings.Enable = true })
+
+		_, err := th.App.CreateUser(newUser("jdoe" + model.NewRandomString(6)))
+		require.Nil(t, err)
+	})
+}
+
+func TestGetUsernamePolicyViolations(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	lastName := "doe" + model.NewRandomString(6)
+	ok := storeUser(t, th, "jane."+lastName, "Jane", lastName, "")
+	badPattern := storeUser(t, th, "jdoe"+model.NewRandomString(6), "", "", "")
+	reserved := storeUser(t, th, "reserved."+model.NewRandomString(6), "", "", "")
+	impostor := storeUser(t, th, "fake.jane"+model.NewRandomString(6), "", "", "Jane "+lastName)
+	ldap := storeUser(t, th, "ldap"+model.NewRandomString(6), "", "", "")
+	ldap.AuthService = model.USER_AUTH_SERVICE_LDAP
+	ldap.AuthData = model.NewString(model.NewId())
+	require.Nil(t, (<-th.App.Srv.Store.User().Update(ldap, true)).Err)
+
+	// The report uses the configured rules even though the policy isn't enabled yet
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.UsernamePolicySettings.UsernamePattern = `[a-z]+\.[a-z0-9]+`
+		*cfg.UsernamePolicySettings.ReservedUsernames = "ceo.office " + reserved.Username
+		*cfg.UsernamePolicySettings.BlockNameImpersonation = true
+	})
+
+	violations, err := th.App.GetUsernamePolicyViolations()
+	require.Nil(t, err)
+
+	byUser := make(map[string][]*model.UsernamePolicyViolation)
+	for _, violation := range violations {
+		byUser[violation.UserId] = append(byUser[violation.UserId], violation)
+	}
+
+	assert.Empty(t, byUser[ok.Id])
+	assert.Empty(t, byUser[ldap.Id], "LDAP users should be exempt")
+
+	require.Len(t, byUser[badPattern.Id], 1)
+	assert.Equal(t, model.USERNAME_POLICY_VIOLATION_PATTERN, byUser[badPattern.Id][0].Violation)
+
+	require.Len(t, byUser[reserved.Id], 1)
+	assert.Equal(t, model.USERNAME_POLICY_VIOLATION_RESERVED, byUser[reserved.Id][0].Violation)
+
+	require.Len(t, byUser[impostor.Id], 1)
+	assert.Equal(t, model.USERNAME_POLICY_VIOLATION_IMPERSONATION, byUser[impostor.Id][0].Violation)
+	assert.Equal(t, []string{ok.Id}, byUser[impostor.Id][0].ImpersonatedUserIds)
+}
+
+// storeUser saves a user directly so that it can break the username policy.
+func storeUser(t *testing.T, th *TestHelper, username, firstName, lastName, nickname string) *model.User {
+	user := &model.User{
+		Email:     "success+" + model.NewId() + "@simulator.amazonses.com",
+		Username:  username,
+		FirstName: firstName,
+		LastName:  lastName,
+		Nickname:  nickname,
+	}
+
+	result := <-th.App.Srv.Store.User().Save(user)
+	require.Nil(t, result.Err)
+	return result.Data.(*model.User)
+}
@@ -5,6 +5,9 @@ package app
 
 import (
 	"fmt"
+	"net"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -20,19 +23,21 @@ const (
 	SEND_SLOW_WARN            = (SEND_QUEUE_SIZE * 50) / 100
 	SEND_DEADLOCK_WARN        = (SEND_QUEUE_SIZE * 95) / 100
 	WRITE_WAIT                = 30 * time.Second
-	PONG_WAIT                 = 100 * time.Second
-	PING_PERIOD               = (PONG_WAIT * 6) / 10
 	AUTH_TIMEOUT              = 5 * time.Second
 	WEBCONN_MEMBER_CACHE_TIME = 1000 * 60 * 30 // 30 minutes
+
+	PREFERENCES_EVENT_COALESCE_WINDOW = 20 * time.Millisecond
 )
 
 type WebConn struct {
 	sessionExpiresAt          int64 // This should stay at the top for 64-bit alignment of 64-bit words accessed atomically
+	droppedEvents             int64
 	App                       *App
 	WebSocket                 *websocket.Conn
 	Send                      chan model.WebSocketMessage
 	sessionToken              atomic.Value
 	session                   atomic.Value
+	activeThreadId            atomic.Value
 	UserId                    string
 	T                         goi18n.TranslateFunc
 	Locale                    string
@@ -41,16 +46,36 @@ type WebConn struct {
 	Sequence                  int64
 	endWritePump              chan struct{}
 	pumpFinished              chan struct{}
+	closeOnce                 sync.Once
+
+	// pingInterval is how often the client is pinged, and reapTimeout is how long after the last pong it's sent that
+	// it's assumed to be gone. They're set from the config when the connection is made.
+	pingInterval time.Duration
+	reapTimeout  time.Duration
+
+	// IpAddress and UserAgent are those of the request that the connection was upgraded from, which a session
+	// that it authenticates with later is checked against.
+	IpAddress string
+	UserAgent string
+
+	// lastBroadcastAt, consecutiveDrops and missedEvents are only used by the hub that the connection belongs to
+	lastBroadcastAt  int64
+	consecutiveDrops int
+	missedEvents     bool
+	unregistered     int32
 }
 
 func (a *App) NewWebConn(ws *websocket.Conn, session model.Session, t goi18n.TranslateFunc, locale string) *WebConn {
-	if len(session.UserId) > 0 {
+	if len(session.UserId) > 0 && !session.IsImpersonation {
 		a.Go(func() {
 			a.SetStatusOnline(session.UserId, session.Id, false)
 			a.UpdateLastActivityAtIfNeeded(session)
 		})
 	}
 
+	settings := a.Config().ServiceSettings
+	pingInterval := time.Duration(*settings.WebsocketPingIntervalSeconds) * time.Second
+
 	wc := &WebConn{
 		App:          a,
 		Send:         make(chan model.WebSocketMessage, SEND_QUEUE_SIZE),
@@ -60,6 +85,9 @@ func (a *App) NewWebConn(ws *websocket.Conn, session model.Session, t goi18n.Tra
 		Locale:       locale,
 		endWritePump: make(chan struct{}, 2),
 		pumpFinished: make(chan struct{}, 1),
+		pingInterval: pingInterval,
+		// The client has until the pong timeout after the last ping that it's allowed to miss to answer one
+		reapTimeout: pingInterval*time.Duration(*settings.WebsocketMaxMissedPongs) + time.Duration(*settings.WebsocketPongTimeoutSeconds)*time.Second,
 	}
 
 	wc.SetSession(&session)
@@ -69,10 +97,14 @@ func (a *App) NewWebConn(ws *websocket.Conn, session model.Session, t goi18n.Tra
 	return wc
 }
 
+// Close closes the connection and waits for it to finish. It's safe to call more than once since a connection that
+// was being moved between hubs may be closed by both of them.
 func (wc *WebConn) Close() {
-	wc.WebSocket.Close()
-	wc.endWritePump <- struct{}{}
-	<-wc.pumpFinished
+	wc.closeOnce.Do(func() {
+		wc.WebSocket.Close()
+		wc.endWritePump <- struct{}{}
+		<-wc.pumpFinished
+	})
 }
 
 func (c *WebConn) GetSessionExpiresAt() int64 {
@@ -103,6 +135,16 @@ func (c *WebConn) SetSession(v *model.Session) {
 	c.session.Store(v)
 }
 
+// GetActiveThreadId returns the id of the thread that's open on the connection, if there is one.
+func (c *WebConn) GetActiveThreadId() string {
+	threadId, _ := c.activeThreadId.Load().(string)
+	return threadId
+}
+
+func (c *WebConn) SetActiveThreadId(v string) {
+	c.activeThreadId.Store(v)
+}
+
 func (c *WebConn) Pump() {
 	ch := make(chan struct{}, 1)
 	go func() {
@@ -121,9 +163,9 @@ func (c *WebConn) readPump() {
 		c.WebSocket.Close()
 	}()
 	c.WebSocket.SetReadLimit(model.SOCKET_MAX_MESSAGE_SIZE_KB)
-	c.WebSocket.SetReadDeadline(time.Now().Add(PONG_WAIT))
+	c.WebSocket.SetReadDeadline(time.Now().Add(c.reapTimeout))
 	c.WebSocket.SetPongHandler(func(string) error {
-		c.WebSocket.SetReadDeadline(time.Now().Add(PONG_WAIT))
+		c.WebSocket.SetReadDeadline(time.Now().Add(c.reapTimeout))
 		if c.IsAuthenticated() {
 			c.App.Go(func() {
 				c.App.SetStatusAwayIfNeeded(c.UserId, false)
@@ -138,6 +180,13 @@ func (c *WebConn) readPump() {
 			// browsers will appear as CloseNoStatusReceived
 			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseNoStatusReceived) {
 				mlog.Debug(fmt.Sprintf("websocket.read: client side closed socket userId=%v", c.UserId))
+			} else if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				// The read deadline is only moved by pongs, so the client has stopped answering pings and is most
+				// likely gone without the connection having been closed, such as when a proxy drops it
+				mlog.Debug(fmt.Sprintf("websocket.read: reaping websocket for userId=%v that stopped answering pings", c.UserId))
+				if metrics := c.App.Metrics; metrics != nil {
+					metrics.IncrementWebsocketConnectionReaped()
+				}
 			} else {
 				mlog.Debug(fmt.Sprintf("websocket.read: closing websocket for userId=%v error=%v", c.UserId, err.Error()))
 			}
@@ -150,7 +199,7 @@ func (c *WebConn) readPump() {
 }
 
 func (c *WebConn) writePump() {
-	ticker := time.NewTicker(PING_PERIOD)
+	ticker := time.NewTicker(c.pingInterval)
 	authTicker := time.NewTicker(AUTH_TIMEOUT)
 
 	defer func() {
@@ -168,57 +217,24 @@ func (c *WebConn) writePump() {
 				return
 			}
 
-			evt, evtOk := msg.(*model.WebSocketEvent)
-
-			skipSend := false
-			if len(c.Send) >= SEND_SLOW_WARN {
-				// When the pump starts to get slow we'll drop non-critical messages
-				if msg.EventType() == model.WEBSOCKET_EVENT_TYPING ||
-					msg.EventType() == model.WEBSOCKET_EVENT_STATUS_CHANGE ||
-					msg.EventType() == model.WEBSOCKET_EVENT_CHANNEL_VIEWED {
-					mlog.Info(fmt.Sprintf("websocket.slow: dropping message userId=%v type=%v channelId=%v", c.UserId, msg.EventType(), evt.Broadcast.ChannelId))
-					skipSend = true
-				}
-			}
-
-			if !skipSend {
-				var msgBytes []byte
-				if evtOk {
-					cpyEvt := &model.WebSocketEvent{}
-					*cpyEvt = *evt
-					cpyEvt.Sequence = c.Sequence
-					msgBytes = []byte(cpyEvt.ToJson())
-					c.Sequence++
-				} else {
-					msgBytes = []byte(msg.ToJson())
-				}
-
-				if len(c.Send) >= SEND_DEADLOCK_WARN {
-					if evtOk {
-						mlog.Error(fmt.Sprintf("websocket.full: message userId=%v type=%v channelId=%v size=%v", c.UserId, msg.EventType(), evt.Broadcast.ChannelId, len(msg.ToJson())))
-					} else {
-						mlog.Error(fmt.Sprintf("websocket.full: message userId=%v type=%v size=%v", c.UserId, msg.EventType(), len(msg.ToJson())))
-					}
+			for msg != nil {
+				var next model.WebSocketMessage
+				closed := false
+				if evt, evtOk := msg.(*model.WebSocketEvent); evtOk && isPreferencesEvent(evt) {
+					msg, next, closed = c.coalescePreferencesEvents(evt)
 				}
 
-				c.WebSocket.SetWriteDeadline(time.Now().Add(WRITE_WAIT))
-				if err := c.WebSocket.WriteMessage(websocket.TextMessage, msgBytes); err != nil {
-					// browsers will appear as CloseNoStatusReceived
-					if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseNoStatusReceived) {
-						mlog.Debug(fmt.Sprintf("websocket.send: client side closed socket userId=%v", c.UserId))
-					} else {
-						mlog.Debug(fmt.Sprintf("websocket.send: closing websocket for userId=%v, error=%v", c.UserId, err.Error()))
-					}
-
+				if !c.writeMessage(msg) {
 					return
 				}
 
-				if c.App.Metrics != nil {
-					c.App.Go(func() {
-						c.App.Metrics.IncrementWebSocketBroadcast(msg.EventType())
-					})
+				if closed {
+					c.WebSocket.SetWriteDeadline(time.Now().Add(WRITE_WAIT))
+					c.WebSocket.WriteMessage(websocket.CloseMessage, []byte{})
+					return
 				}
 
+				msg = next
 			}
 		case <-ticker.C:
 			c.WebSocket.SetWriteDeadline(time.Now().Add(WRITE_WAIT))
@@ -244,6 +260,139 @@ func (c *WebConn) writePump() {
 	}
 }
 
+// writeMessage writes the message to the websocket, returning false if the connection should be closed.
+func (c *WebConn) writeMessage(msg model.WebSocketMessage) bool {
+	evt, evtOk := msg.(*model.WebSocketEvent)
+
+	if len(c.Send) >= SEND_SLOW_WARN {
+		// When the pump starts to get slow we'll drop non-critical messages
+		if msg.EventType() == model.WEBSOCKET_EVENT_TYPING ||
+			msg.EventType() == model.WEBSOCKET_EVENT_STATUS_CHANGE ||
+			msg.EventType() == model.WEBSOCKET_EVENT_CHANNEL_VIEWED {
+			mlog.Info(fmt.Sprintf("websocket.slow: dropping message userId=%v type=%v channelId=%v", c.UserId, msg.EventType(), evt.Broadcast.ChannelId))
+			return true
+		}
+	}
+
+	var msgBytes []byte
+	if evtOk {
+		cpyEvt := &model.WebSocketEvent{}
+		*cpyEvt = *evt
+		cpyEvt.Sequence = c.Sequence
+		msgBytes = []byte(cpyEvt.ToJson())
+		c.Sequence++
+	} else {
+		msgBytes = []byte(msg.ToJson())
+	}
+
+	if len(c.Send) >= SEND_DEADLOCK_WARN {
+		if evtOk {
+			mlog.Error(fmt.Sprintf("websocket.full: message userId=%v type=%v channelId=%v size=%v", c.UserId, msg.EventType(), evt.Broadcast.ChannelId, len(msg.ToJson())))
+		} else {
+			mlog.Error(fmt.Sprintf("websocket.full: message userId=%v type=%v size=%v", c.UserId, msg.EventType(), len(msg.ToJson())))
+		}
+	}
+
+	c.WebSocket.SetWriteDeadline(time.Now().Add(WRITE_WAIT))
+	if err := c.WebSocket.WriteMessage(websocket.TextMessage, msgBytes); err != nil {
+		// browsers will appear as CloseNoStatusReceived
+		if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseNoStatusReceived) {
+			mlog.Debug(fmt.Sprintf("websocket.send: client side closed socket userId=%v", c.UserId))
+		} else {
+			mlog.Debug(fmt.Sprintf("websocket.send: closing websocket for userId=%v, error=%v", c.UserId, err.Error()))
+		}
+
+		return false
+	}
+
+	if c.App.Metrics != nil {
+		c.App.Go(func() {
+			c.App.Metrics.IncrementWebSocketBroadcast(msg.EventType())
+		})
+	}
+
+	if evtOk && c.disconnectsForMaintenance(evt) {
+		mlog.Debug(fmt.Sprintf("websocket.send: closing websocket for maintenance mode userId=%v", c.UserId))
+		c.closeForMaintenance()
+		return false
+	}
+
+	if evtOk && evt.Event == model.WEBSOCKET_EVENT_RECONNECT {
+		mlog.Debug(fmt.Sprintf("websocket.send: closing websocket for draining the server userId=%v", c.UserId))
+		c.closeForDrain()
+		return false
+	}
+
+	return true
+}
+
+func isPreferencesEvent(evt *model.WebSocketEvent) bool {
+	return evt.Event == model.WEBSOCKET_EVENT_PREFERENCES_CHANGED || evt.Event == model.WEBSOCKET_EVENT_PREFERENCES_DELETED
+}
+
+// coalescePreferencesEvents merges the events of the same type as evt that are queued for the connection within
+// PREFERENCES_EVENT_COALESCE_WINDOW into one, so that clients don't process a burst of preference changes one event
+// at a time. It returns the merged event, the first message after it that couldn't be merged, if any, and whether
+// the connection's queue was closed.
+func (c *WebConn) coalescePreferencesEvents(evt *model.WebSocketEvent) (model.WebSocketMessage, model.WebSocketMessage, bool) {
+	data, _ := evt.Data["preferences"].(string)
+	preferences, err := model.PreferencesFromJson(strings.NewReader(data))
+	if err != nil {
+		return evt, nil, false
+	}
+
+	size := len(data)
+	merged := false
+	result := func() *model.WebSocketEvent {
+		if !merged {
+			return evt
+		}
+
+		mergedEvt := &model.WebSocketEvent{}
+		*mergedEvt = *evt
+		mergedEvt.Data = make(map[string]interface{}, len(evt.Data))
+		for key, value := range evt.Data {
+			mergedEvt.Data[key] = value
+		}
+		mergedEvt.Data["preferences"] = preferences.ToJson()
+
+		return mergedEvt
+	}
+
+	timer := time.NewTimer(PREFERENCES_EVENT_COALESCE_WINDOW)
+	defer timer.Stop()
+
+	for {
+		select {
+		case next, ok := <-c.Send:
+			if !ok {
+				return result(), nil, true
+			}
+
+			nextEvt, evtOk := next.(*model.WebSocketEvent)
+			if !evtOk || nextEvt.Event != evt.Event {
+				return result(), next, false
+			}
+
+			nextData, _ := nextEvt.Data["preferences"].(string)
+			if size+len(nextData) > PREFERENCES_EVENT_MAX_SIZE {
+				return result(), next, false
+			}
+
+			nextPreferences, err := model.PreferencesFromJson(strings.NewReader(nextData))
+			if err != nil {
+				return result(), next, false
+			}
+
+			preferences = append(preferences, nextPreferences...)
+			size += len(nextData)
+			merged = true
+		case <-timer.C:
+			return result(), nil, false
+		}
+	}
+}
+
 func (webCon *WebConn) InvalidateCache() {
 	webCon.AllChannelMembers = nil
 	webCon.LastAllChannelMembersTime = 0
@@ -274,6 +423,90 @@ func (webCon *WebConn) IsAuthenticated() bool {
 	return true
 }
 
+// disconnectsForMaintenance returns whether the connection should be closed now that it has been sent the event,
+// which is the case for everyone but system admins once maintenance mode has been enabled, unless it's read-only.
+func (webCon *WebConn) disconnectsForMaintenance(evt *model.WebSocketEvent) bool {
+	if evt.Event != model.WEBSOCKET_EVENT_MAINTENANCE_MODE {
+		return false
+	}
+
+	if enabled, _ := evt.Data["enabled"].(bool); !enabled {
+		return false
+	}
+
+	if readOnly, _ := evt.Data["read_only"].(bool); readOnly {
+		return false
+	}
+
+	session := webCon.GetSession()
+	return session == nil || !webCon.App.SessionHasPermissionTo(*session, model.PERMISSION_MANAGE_SYSTEM)
+}
+
+// closeForMaintenance tells the client that the server is down for maintenance so that it waits before reconnecting.
+func (webCon *WebConn) closeForMaintenance() {
+	closeMessage := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "maintenance")
+	webCon.WebSocket.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(WRITE_WAIT))
+	webCon.WebSocket.Close()
+}
+
+// closeForDrain tells the client that the server is going away so that it reconnects straight away, which it'll do
+// to another server since this one refuses new connections while it's draining.
+func (webCon *WebConn) closeForDrain() {
+	closeMessage := websocket.FormatCloseMessage(websocket.CloseServiceRestart, "draining")
+	webCon.WebSocket.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(WRITE_WAIT))
+	webCon.WebSocket.Close()
+}
+
+// queueEvent adds the event to the connection's send queue without waiting, returning false if the queue is full.
+// An event that doesn't fit is dropped, and the next one to fit is preceded by a missed events event so that the
+// client knows to fetch what it missed.
+func (webCon *WebConn) queueEvent(msg *model.WebSocketEvent) bool {
+	if webCon.missedEvents {
+		missed := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_MISSED_EVENTS, "", "", webCon.UserId, nil)
+
+		select {
+		case webCon.Send <- missed:
+			webCon.missedEvents = false
+		default:
+			webCon.dropEvent(msg)
+			return false
+		}
+	}
+
+	select {
+	case webCon.Send <- msg:
+		webCon.consecutiveDrops = 0
+
+		if metrics := webCon.App.Metrics; metrics != nil {
+			metrics.ObserveWebsocketConnectionQueueDepth(len(webCon.Send))
+		}
+
+		return true
+	default:
+		webCon.dropEvent(msg)
+		return false
+	}
+}
+
+func (webCon *WebConn) dropEvent(msg *model.WebSocketEvent) {
+	if webCon.consecutiveDrops == 0 {
+		mlog.Debug(fmt.Sprintf("websocket.slow: send queue is full, dropping events userId=%v type=%v", webCon.UserId, msg.Event))
+	}
+
+	webCon.missedEvents = true
+	webCon.consecutiveDrops++
+	atomic.AddInt64(&webCon.droppedEvents, 1)
+
+	if metrics := webCon.App.Metrics; metrics != nil {
+		metrics.IncrementWebsocketConnectionEventDropped()
+	}
+}
+
+// DroppedEvents returns the number of events that have been dropped because the connection's send queue was full.
+func (webCon *WebConn) DroppedEvents() int64 {
+	return atomic.LoadInt64(&webCon.droppedEvents)
+}
+
 func (webCon *WebConn) SendHello() {
 	msg := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_HELLO, "", "", webCon.UserId, nil)
 	msg.Add("server_version", fmt.Sprintf("%v.%v.%v.%v", model.CurrentVersion, model.BuildNumber, webCon.App.ClientConfigHash(), webCon.App.License() != nil))
@@ -324,6 +557,13 @@ func (webCon *WebConn) ShouldSendEvent(msg *model.WebSocketEvent) bool {
 		}
 	}
 
+	// Only report thread events to users who are following the thread or who have it open
+	if len(msg.Broadcast.ThreadId) > 0 {
+		if !msg.Broadcast.ThreadFollowers[webCon.UserId] && webCon.GetActiveThreadId() != msg.Broadcast.ThreadId {
+			return false
+		}
+	}
+
 	// Only report events to users who are in the channel for the event
 	if len(msg.Broadcast.ChannelId) > 0 {
 		if model.GetMillis()-webCon.LastAllChannelMembersTime > WEBCONN_MEMBER_CACHE_TIME {
@@ -4,8 +4,13 @@
 package app
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -55,6 +60,7 @@ func TestWebConnShouldSendEvent(t *testing.T) {
 	adminUserWc.SetSession(session3)
 	adminUserWc.SetSessionToken(session3.Token)
 	adminUserWc.SetSessionExpiresAt(session3.ExpiresAt)
+	adminUserWc.SetActiveThreadId(th.BasicPost.Id)
 
 	cases := []struct {
 		Description   string
@@ -69,6 +75,8 @@ func TestWebConnShouldSendEvent(t *testing.T) {
 		{"should only send to admin", &model.WebsocketBroadcast{ContainsSensitiveData: true}, false, false, true},
 		{"should only send to non-admins", &model.WebsocketBroadcast{ContainsSanitizedData: true}, true, true, false},
 		{"should send to nobody", &model.WebsocketBroadcast{ContainsSensitiveData: true, ContainsSanitizedData: true}, false, false, false},
+		{"should only send to thread followers and viewers", &model.WebsocketBroadcast{ThreadId: th.BasicPost.Id, ThreadFollowers: map[string]bool{th.BasicUser.Id: true}}, true, false, true},
+		{"should not send to viewers of other threads", &model.WebsocketBroadcast{ThreadId: model.NewId()}, false, false, false},
 		// needs more cases to get full coverage
 	}
 
@@ -80,3 +88,144 @@ func TestWebConnShouldSendEvent(t *testing.T) {
 		assert.Equal(t, c.AdminExpected, adminUserWc.ShouldSendEvent(event), c.Description)
 	}
 }
+
+func TestWebConnCoalescePreferencesEvents(t *testing.T) {
+	userId := model.NewId()
+	newPreferencesEvent := func(eventType string, count int) (*model.WebSocketEvent, model.Preferences) {
+		var preferences model.Preferences
+		for i := 0; i < count; i++ {
+			preferences = append(preferences, model.Preference{UserId: userId, Category: model.PREFERENCE_CATEGORY_FLAGGED_POST, Name: model.NewId(), Value: "true"})
+		}
+
+		event := model.NewWebSocketEvent(eventType, "", "", userId, nil)
+		event.Add("preferences", preferences.ToJson())
+		return event, preferences
+	}
+
+	getPreferences := func(t *testing.T, msg model.WebSocketMessage) model.Preferences {
+		preferences, err := model.PreferencesFromJson(strings.NewReader(msg.(*model.WebSocketEvent).Data["preferences"].(string)))
+		require.Nil(t, err)
+		return preferences
+	}
+
+	t.Run("merges queued events", func(t *testing.T) {
+		wc := &WebConn{Send: make(chan model.WebSocketMessage, 10)}
+
+		first, firstPreferences := newPreferencesEvent(model.WEBSOCKET_EVENT_PREFERENCES_CHANGED, 2)
+		second, secondPreferences := newPreferencesEvent(model.WEBSOCKET_EVENT_PREFERENCES_CHANGED, 3)
+		deleted, _ := newPreferencesEvent(model.WEBSOCKET_EVENT_PREFERENCES_DELETED, 1)
+		wc.Send <- second
+		wc.Send <- deleted
+
+		merged, next, closed := wc.coalescePreferencesEvents(first)
+		assert.Equal(t, append(firstPreferences, secondPreferences...), getPreferences(t, merged))
+		assert.Equal(t, deleted, next, "events of another type shouldn't be merged")
+		assert.False(t, closed)
+		assert.Len(t, getPreferences(t, first), 2, "the queued event shouldn't be changed since it's shared with other connections")
+	})
+
+	t.Run("waits for the window", func(t *testing.T) {
+		wc := &WebConn{Send: make(chan model.WebSocketMessage, 10)}
+
+		first, _ := newPreferencesEvent(model.WEBSOCKET_EVENT_PREFERENCES_DELETED, 1)
+		second, _ := newPreferencesEvent(model.WEBSOCKET_EVENT_PREFERENCES_DELETED, 1)
+		go func() {
+			time.Sleep(PREFERENCES_EVENT_COALESCE_WINDOW / 4)
+			wc.Send <- second
+		}()
+
+		merged, next, _ := wc.coalescePreferencesEvents(first)
+		assert.Len(t, getPreferences(t, merged), 2)
+		assert.Nil(t, next)
+	})
+
+	t.Run("size cap", func(t *testing.T) {
+		wc := &WebConn{Send: make(chan model.WebSocketMessage, 10)}
+
+		first, _ := newPreferencesEvent(model.WEBSOCKET_EVENT_PREFERENCES_CHANGED, 1)
+		large, _ := newPreferencesEvent(model.WEBSOCKET_EVENT_PREFERENCES_CHANGED, 0)
+		large.Add("preferences", strings.Repeat(" ", PREFERENCES_EVENT_MAX_SIZE)+"[]")
+		wc.Send <- large
+
+		merged, next, _ := wc.coalescePreferencesEvents(first)
+		assert.Equal(t, first, merged)
+		assert.Equal(t, large, next, "events that would be too large together shouldn't be merged")
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		wc := &WebConn{Send: make(chan model.WebSocketMessage, 10)}
+
+		first, _ := newPreferencesEvent(model.WEBSOCKET_EVENT_PREFERENCES_CHANGED, 1)
+		close(wc.Send)
+
+		merged, next, closed := wc.coalescePreferencesEvents(first)
+		assert.Equal(t, first, merged)
+		assert.Nil(t, next)
+		assert.True(t, closed)
+	})
+}
+
+// unresponsiveWebsocketHandler accepts connections but never answers pings, like a client that's gone without its
+// connection having been closed.
+func unresponsiveWebsocketHandler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		upgrader := &websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+		}
+		conn, err := upgrader.Upgrade(w, req, nil)
+		require.NoError(t, err)
+
+		conn.SetPingHandler(func(string) error { return nil })
+		for err == nil {
+			_, _, err = conn.ReadMessage()
+		}
+	}
+}
+
+func TestWebConnReapsConnectionsThatMissPongs(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.ServiceSettings.WebsocketPingIntervalSeconds = 1
+		*cfg.ServiceSettings.WebsocketPongTimeoutSeconds = 1
+		*cfg.ServiceSettings.WebsocketMaxMissedPongs = 1
+	})
+
+	responsive := httptest.NewServer(dummyWebsocketHandler(t))
+	defer responsive.Close()
+	unresponsive := httptest.NewServer(unresponsiveWebsocketHandler(t))
+	defer unresponsive.Close()
+
+	th.App.HubStart()
+	defer th.App.HubStop()
+
+	registerDummyWebConn(t, th.App, responsive.Listener.Addr(), th.BasicUser.Id)
+	registerDummyWebConn(t, th.App, unresponsive.Listener.Addr(), th.BasicUser2.Id)
+
+	waitForStatus := func(userId string, status string) {
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			if current, err := th.App.GetStatus(userId); err == nil && current.Status == status {
+				return
+			}
+			require.True(t, time.Now().Before(deadline), "timed out waiting for %v to be %v", userId, status)
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+
+	waitForStatus(th.BasicUser.Id, model.STATUS_ONLINE)
+	waitForStatus(th.BasicUser2.Id, model.STATUS_ONLINE)
+
+	// the unresponsive connection is reaped two seconds after it was made, once it's missed a pong
+	waitForStatus(th.BasicUser2.Id, model.STATUS_OFFLINE)
+	assert.Equal(t, 1, th.App.TotalWebsocketConnections())
+
+	// while the other keeps answering pings
+	time.Sleep(2 * time.Second)
+	assert.Equal(t, 1, th.App.TotalWebsocketConnections())
+	status, err := th.App.GetStatus(th.BasicUser.Id)
+	require.Nil(t, err)
+	assert.Equal(t, model.STATUS_ONLINE, status.Status)
+}
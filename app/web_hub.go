@@ -5,11 +5,11 @@ package app
 
 import (
 	"fmt"
-	"hash/fnv"
 	"runtime"
 	"runtime/debug"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -21,17 +21,23 @@ const (
 	BROADCAST_QUEUE_SIZE = 4096
 	DEADLOCK_TICKER      = 15 * time.Second                  // check every 15 seconds
 	DEADLOCK_WARN        = (BROADCAST_QUEUE_SIZE * 99) / 100 // number of buffered messages before printing stack trace
+
+	HUB_REBALANCE_MAX_MOVES = 1000 // most connections moved out of a single hub each time the hubs are rebalanced
 )
 
 type Hub struct {
-	// connectionCount should be kept first.
+	// connectionCount and droppedEvents should be kept first.
 	// See https://github.com/mattermost/mattermost-server/pull/7281
 	connectionCount int64
+	droppedEvents   int64
 	app             *App
+	registry        *hubRegistry
 	connectionIndex int
 	register        chan *WebConn
 	unregister      chan *WebConn
-	broadcast       chan *model.WebSocketEvent
+	broadcast       chan hubMessage
+	activate        chan *hubMigration
+	rebalance       chan hubRebalance
 	stop            chan struct{}
 	didStop         chan struct{}
 	invalidateUser  chan string
@@ -39,12 +45,44 @@ type Hub struct {
 	goroutineId     int
 }
 
+// hubMessage is either an event to broadcast or a step in moving a connection from one hub to another. Both go
+// through the same queue so that a connection that's being moved is sent every event once and in order.
+type hubMessage struct {
+	event   *model.WebSocketEvent
+	adopt   *hubMigration
+	release *hubMigration
+
+	// subscribers are the hub's connections that are subscribed to the presence of the user that a status event is
+	// about, in addition to the ones that the event is sent to anyway
+	subscribers []*WebConn
+}
+
+// hubMigration moves a connection to another hub. Events for the connection that were queued before the move are
+// sent by the hub that it's leaving, while the hub that it's joining holds on to any newer ones until it's told that
+// the old hub is done with the connection.
+type hubMigration struct {
+	conn   *WebConn
+	target *Hub
+
+	// closed is set if the old hub had to close the connection before the move finished
+	closed bool
+}
+
+// hubRebalance asks a hub to move up to count of its idle connections to the target hub.
+type hubRebalance struct {
+	target     *Hub
+	count      int
+	idleBefore int64
+}
+
 func (a *App) NewWebHub() *Hub {
 	return &Hub{
 		app:            a,
 		register:       make(chan *WebConn, 1),
 		unregister:     make(chan *WebConn, 1),
-		broadcast:      make(chan *model.WebSocketEvent, BROADCAST_QUEUE_SIZE),
+		broadcast:      make(chan hubMessage, BROADCAST_QUEUE_SIZE),
+		activate:       make(chan *hubMigration),
+		rebalance:      make(chan hubRebalance),
 		stop:           make(chan struct{}),
 		didStop:        make(chan struct{}),
 		invalidateUser: make(chan string),
@@ -62,8 +100,11 @@ func (a *App) TotalWebsocketConnections() int {
 }
 
 func (a *App) HubStart() {
-	// Total number of hubs is twice the number of CPUs.
-	numberOfHubs := runtime.NumCPU() * 2
+	numberOfHubs := *a.Config().ServiceSettings.WebsocketHubs
+	if numberOfHubs == 0 {
+		// By default, the total number of hubs is twice the number of CPUs.
+		numberOfHubs = runtime.NumCPU() * 2
+	}
 	mlog.Info(fmt.Sprintf("Starting %v websocket hubs", numberOfHubs))
 
 	a.Hubs = make([]*Hub, numberOfHubs)
@@ -72,12 +113,30 @@ func (a *App) HubStart() {
 	for i := 0; i < len(a.Hubs); i++ {
 		a.Hubs[i] = a.NewWebHub()
 		a.Hubs[i].connectionIndex = i
-		a.Hubs[i].Start()
 	}
 
+	registry := newHubRegistry(a.Hubs)
+	a.hubRegistry = registry
+
+	for _, hub := range a.Hubs {
+		hub.registry = registry
+		hub.Start()
+	}
+
+	a.startWebSocketOutbox()
+
+	rebalanceInterval := time.Duration(*a.Config().ServiceSettings.WebsocketHubRebalanceIntervalSeconds) * time.Second
+
 	go func() {
 		ticker := time.NewTicker(DEADLOCK_TICKER)
 
+		var rebalance <-chan time.Time
+		if rebalanceInterval > 0 {
+			rebalanceTicker := time.NewTicker(rebalanceInterval)
+			defer rebalanceTicker.Stop()
+			rebalance = rebalanceTicker.C
+		}
+
 		defer func() {
 			ticker.Stop()
 		}()
@@ -85,7 +144,11 @@ func (a *App) HubStart() {
 		for {
 			select {
 			case <-ticker.C:
-				for _, hub := range a.Hubs {
+				for _, hub := range registry.all {
+					if metrics := a.Metrics; metrics != nil {
+						metrics.SetWebsocketHubQueueDepth(hub.connectionIndex, len(hub.broadcast))
+					}
+
 					if len(hub.broadcast) >= DEADLOCK_WARN {
 						mlog.Error(fmt.Sprintf("Hub processing might be deadlock on hub %v goroutine %v with %v events in the buffer", hub.connectionIndex, hub.goroutineId, len(hub.broadcast)))
 						buf := make([]byte, 1<<16)
@@ -101,6 +164,10 @@ func (a *App) HubStart() {
 					}
 				}
 
+			case <-rebalance:
+				// Connections that haven't been sent anything since the last rebalance are considered idle
+				registry.rebalance(model.GetMillis() - int64(rebalanceInterval/time.Millisecond))
+
 			case <-a.HubsStopCheckingForDeadlock:
 				return
 			}
@@ -111,6 +178,9 @@ func (a *App) HubStart() {
 func (a *App) HubStop() {
 	mlog.Info("stopping websocket hub connections")
 
+	// The outbox sends what's left in its queue before the hubs that it sends to are stopped
+	a.stopWebSocketOutbox()
+
 	select {
 	case a.HubsStopCheckingForDeadlock <- true:
 	default:
@@ -122,30 +192,32 @@ func (a *App) HubStop() {
 	}
 
 	a.Hubs = []*Hub{}
+	a.hubRegistry = nil
 }
 
-func (a *App) GetHubForUserId(userId string) *Hub {
-	if len(a.Hubs) == 0 {
-		return nil
-	}
-
-	hash := fnv.New32a()
-	hash.Write([]byte(userId))
-	index := hash.Sum32() % uint32(len(a.Hubs))
-	return a.Hubs[index]
-}
-
+// HubRegister adds the connection to the hub with the fewest connections.
 func (a *App) HubRegister(webConn *WebConn) {
-	hub := a.GetHubForUserId(webConn.UserId)
+	hub := a.hubRegistry.add(webConn)
 	if hub != nil {
 		hub.Register(webConn)
 	}
 }
 
 func (a *App) HubUnregister(webConn *WebConn) {
-	hub := a.GetHubForUserId(webConn.UserId)
-	if hub != nil {
-		hub.Unregister(webConn)
+	atomic.StoreInt32(&webConn.unregistered, 1)
+
+	// this also drops any presence subscriptions that the connection had
+	hub, lastForUser := a.hubRegistry.remove(webConn)
+	if hub == nil {
+		return
+	}
+
+	hub.Unregister(webConn)
+
+	if lastForUser && len(webConn.UserId) > 0 {
+		a.Go(func() {
+			a.SetStatusOffline(webConn.UserId, false)
+		})
 	}
 }
 
@@ -176,16 +248,7 @@ func (a *App) Publish(message *model.WebSocketEvent) {
 }
 
 func (a *App) PublishSkipClusterSend(message *model.WebSocketEvent) {
-	if message.Broadcast.UserId != "" {
-		hub := a.GetHubForUserId(message.Broadcast.UserId)
-		if hub != nil {
-			hub.Broadcast(message)
-		}
-	} else {
-		for _, hub := range a.Hubs {
-			hub.Broadcast(message)
-		}
-	}
+	a.hubRegistry.broadcast(message)
 }
 
 func (a *App) InvalidateCacheForChannel(channel *model.Channel) {
@@ -300,8 +363,7 @@ func (a *App) InvalidateCacheForUserSkipClusterSend(userId string) {
 	a.Srv.Store.User().InvalidateProfilesInChannelCacheByUser(userId)
 	a.Srv.Store.User().InvalidatProfileCacheForUser(userId)
 
-	hub := a.GetHubForUserId(userId)
-	if hub != nil {
+	for _, hub := range a.hubRegistry.hubsForUser(userId) {
 		hub.InvalidateUser(userId)
 	}
 }
@@ -324,8 +386,7 @@ func (a *App) InvalidateCacheForWebhookSkipClusterSend(webhookId string) {
 }
 
 func (a *App) InvalidateWebConnSessionCacheForUser(userId string) {
-	hub := a.GetHubForUserId(userId)
-	if hub != nil {
+	for _, hub := range a.hubRegistry.hubsForUser(userId) {
 		hub.InvalidateUser(userId)
 	}
 }
@@ -345,12 +406,32 @@ func (h *Hub) Unregister(webConn *WebConn) {
 	}
 }
 
+// Broadcast queues the event to be sent to the hub's connections. The event is dropped if the hub's queue is full
+// so that a hub that's fallen behind doesn't hold up everything else.
 func (h *Hub) Broadcast(message *model.WebSocketEvent) {
+	h.broadcastToSubscribers(message, nil)
+}
+
+func (h *Hub) broadcastToSubscribers(message *model.WebSocketEvent, subscribers []*WebConn) {
 	if h != nil && h.broadcast != nil && message != nil {
-		h.broadcast <- message
+		select {
+		case h.broadcast <- hubMessage{event: message, subscribers: subscribers}:
+		default:
+			atomic.AddInt64(&h.droppedEvents, 1)
+			if metrics := h.app.Metrics; metrics != nil {
+				metrics.IncrementWebsocketHubEventDropped(h.connectionIndex)
+			}
+
+			mlog.Error(fmt.Sprintf("webhub.broadcast: queue is full, dropping event hub=%v type=%v", h.connectionIndex, message.Event))
+		}
 	}
 }
 
+// DroppedEvents returns the number of events that have been dropped because the hub's queue was full.
+func (h *Hub) DroppedEvents() int64 {
+	return atomic.LoadInt64(&h.droppedEvents)
+}
+
 func (h *Hub) InvalidateUser(userId string) {
 	h.invalidateUser <- userId
 }
@@ -382,45 +463,122 @@ func (h *Hub) Start() {
 
 		connections := newHubConnectionIndex()
 
+		// connections that are being moved to this hub, along with the events for them that are waiting on the
+		// hub that they're leaving to finish with them
+		pending := make(map[*WebConn][]*model.WebSocketEvent)
+
+		updateConnectionCount := func() {
+			atomic.StoreInt64(&h.connectionCount, int64(len(connections.All())))
+		}
+
+		send := func(webCon *WebConn, msg *model.WebSocketEvent, now int64) {
+			if events, ok := pending[webCon]; ok {
+				pending[webCon] = append(events, msg)
+				return
+			}
+
+			if webCon.queueEvent(msg) {
+				webCon.lastBroadcastAt = now
+				return
+			}
+
+			settings := h.app.Config().ServiceSettings
+			if *settings.WebsocketSlowClientPolicy != model.WEBSOCKET_SLOW_CLIENT_POLICY_DISCONNECT || webCon.consecutiveDrops < *settings.WebsocketSlowClientMaxDrops {
+				return
+			}
+
+			mlog.Error(fmt.Sprintf("webhub.broadcast: cannot send, closing websocket for userId=%v drops=%v", webCon.UserId, webCon.consecutiveDrops))
+			if metrics := h.app.Metrics; metrics != nil {
+				metrics.IncrementWebsocketSlowClientDisconnect()
+			}
+
+			close(webCon.Send)
+			connections.Remove(webCon)
+			updateConnectionCount()
+		}
+
 		for {
 			select {
 			case webCon := <-h.register:
 				connections.Add(webCon)
-				atomic.StoreInt64(&h.connectionCount, int64(len(connections.All())))
+				updateConnectionCount()
 			case webCon := <-h.unregister:
 				connections.Remove(webCon)
-				atomic.StoreInt64(&h.connectionCount, int64(len(connections.All())))
-
-				if len(webCon.UserId) == 0 {
-					continue
-				}
-
-				if len(connections.ForUser(webCon.UserId)) == 0 {
-					h.app.Go(func() {
-						h.app.SetStatusOffline(webCon.UserId, false)
-					})
-				}
+				delete(pending, webCon)
+				updateConnectionCount()
 			case userId := <-h.invalidateUser:
 				for _, webCon := range connections.ForUser(userId) {
 					webCon.InvalidateCache()
 				}
 			case msg := <-h.broadcast:
-				candidates := connections.All()
-				if msg.Broadcast.UserId != "" {
-					candidates = connections.ForUser(msg.Broadcast.UserId)
-				}
-				msg.PrecomputeJSON()
-				for _, webCon := range candidates {
-					if webCon.ShouldSendEvent(msg) {
+				switch {
+				case msg.adopt != nil:
+					// the connection may have been closed since it started moving here
+					if atomic.LoadInt32(&msg.adopt.conn.unregistered) == 0 {
+						connections.Add(msg.adopt.conn)
+						pending[msg.adopt.conn] = nil
+						updateConnectionCount()
+					}
+				case msg.release != nil:
+					migration := msg.release
+					if connections.Has(migration.conn) {
+						connections.Remove(migration.conn)
+						updateConnectionCount()
+					} else {
+						migration.closed = true
+					}
+
+					go func() {
 						select {
-						case webCon.Send <- msg:
-						default:
-							mlog.Error(fmt.Sprintf("webhub.broadcast: cannot send, closing websocket for userId=%v", webCon.UserId))
-							close(webCon.Send)
-							connections.Remove(webCon)
+						case migration.target.activate <- migration:
+						case <-migration.target.stop:
+						}
+					}()
+				default:
+					event := msg.event
+					candidates := connections.All()
+					if event.Broadcast.UserId != "" {
+						candidates = connections.ForUser(event.Broadcast.UserId)
+					}
+					now := model.GetMillis()
+					for _, webCon := range candidates {
+						if webCon.ShouldSendEvent(event) {
+							send(webCon, event, now)
+						}
+					}
+
+					if len(msg.subscribers) > 0 && *h.app.Config().ServiceSettings.EnablePresenceSubscriptions {
+						for _, webCon := range msg.subscribers {
+							// the user's own connections have already been sent the event
+							if connections.Has(webCon) && webCon.UserId != event.Broadcast.UserId && webCon.IsAuthenticated() {
+								send(webCon, event, now)
+							}
 						}
 					}
 				}
+			case migration := <-h.activate:
+				events, ok := pending[migration.conn]
+				if !ok {
+					// the connection was closed while it was being moved
+					continue
+				}
+				delete(pending, migration.conn)
+
+				if migration.closed {
+					connections.Remove(migration.conn)
+					updateConnectionCount()
+					continue
+				}
+
+				now := model.GetMillis()
+				for _, event := range events {
+					if !connections.Has(migration.conn) {
+						break
+					}
+					send(migration.conn, event, now)
+				}
+			case request := <-h.rebalance:
+				h.moveIdleConnections(connections, pending, request)
 			case <-h.stop:
 				userIds := make(map[string]bool)
 
@@ -463,6 +621,400 @@ func (h *Hub) Start() {
 	go doRecoverableStart()
 }
 
+// moveIdleConnections starts moving connections that haven't been sent anything since request.idleBefore to the
+// target hub. Each move is queued on both hubs while the registry is locked, so every event published before the
+// move is sent by this hub and every event published after it is sent by the target.
+func (h *Hub) moveIdleConnections(connections *hubConnectionIndex, pending map[*WebConn][]*model.WebSocketEvent, request hubRebalance) {
+	if request.target == h {
+		return
+	}
+
+	h.registry.lock.Lock()
+	defer h.registry.lock.Unlock()
+
+	moved := 0
+	for _, webCon := range connections.All() {
+		if moved >= request.count {
+			break
+		}
+
+		if _, ok := pending[webCon]; ok || webCon.lastBroadcastAt > request.idleBefore || len(webCon.Send) > 0 {
+			continue
+		}
+
+		if h.registry.hubs[webCon] != h {
+			// the connection has already been closed
+			continue
+		}
+
+		// the steps of a move can't be dropped, so both queues need room for them
+		if len(h.broadcast) >= cap(h.broadcast) || len(request.target.broadcast) >= cap(request.target.broadcast) {
+			break
+		}
+
+		migration := &hubMigration{conn: webCon, target: request.target}
+		request.target.broadcast <- hubMessage{adopt: migration}
+		h.broadcast <- hubMessage{release: migration}
+
+		h.registry.unassign(webCon)
+		h.registry.assign(webCon, request.target)
+		moved++
+	}
+
+	if moved > 0 {
+		mlog.Debug(fmt.Sprintf("Moving %v idle websocket connections from hub %v to hub %v", moved, h.connectionIndex, request.target.connectionIndex))
+	}
+}
+
+// hubRegistry keeps track of which hub each connection belongs to. Connections are added to whichever hub has the
+// fewest of them, so a user's connections may be spread across several hubs.
+//
+// It also keeps track of the users whose presence each connection has subscribed to, so that status events can be
+// sent to the hubs of the connections that are interested in them.
+type hubRegistry struct {
+	lock     sync.RWMutex
+	all      []*Hub
+	hubs     map[*WebConn]*Hub
+	load     map[*Hub]int
+	userHubs map[string]map[*Hub]int
+
+	// presenceSubscriptions is the set of user ids that each connection is subscribed to, and presenceSubscribers
+	// is the reverse of it
+	presenceSubscriptions map[*WebConn]map[string]bool
+	presenceSubscribers   map[string]map[*WebConn]bool
+}
+
+func newHubRegistry(hubs []*Hub) *hubRegistry {
+	return &hubRegistry{
+		all:                   hubs,
+		hubs:                  make(map[*WebConn]*Hub),
+		load:                  make(map[*Hub]int, len(hubs)),
+		userHubs:              make(map[string]map[*Hub]int),
+		presenceSubscriptions: make(map[*WebConn]map[string]bool),
+		presenceSubscribers:   make(map[string]map[*WebConn]bool),
+	}
+}
+
+// add assigns the connection to the hub with the fewest connections, returning nil if it's already been added.
+func (r *hubRegistry) add(webConn *WebConn) *Hub {
+	if r == nil || len(r.all) == 0 {
+		return nil
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.hubs[webConn]; ok {
+		return nil
+	}
+
+	hub := r.all[0]
+	for _, candidate := range r.all[1:] {
+		if r.load[candidate] < r.load[hub] {
+			hub = candidate
+		}
+	}
+
+	r.assign(webConn, hub)
+
+	return hub
+}
+
+// remove forgets about the connection, returning the hub that it belonged to and whether it was the user's last
+// connection.
+func (r *hubRegistry) remove(webConn *WebConn) (*Hub, bool) {
+	if r == nil {
+		return nil, false
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	hub := r.unassign(webConn)
+	_, connected := r.userHubs[webConn.UserId]
+
+	r.removePresenceSubscriptions(webConn, nil)
+
+	return hub, hub != nil && !connected
+}
+
+func (r *hubRegistry) assign(webConn *WebConn, hub *Hub) {
+	r.hubs[webConn] = hub
+	r.load[hub]++
+
+	userHubs, ok := r.userHubs[webConn.UserId]
+	if !ok {
+		userHubs = make(map[*Hub]int)
+		r.userHubs[webConn.UserId] = userHubs
+	}
+	userHubs[hub]++
+}
+
+func (r *hubRegistry) unassign(webConn *WebConn) *Hub {
+	hub, ok := r.hubs[webConn]
+	if !ok {
+		return nil
+	}
+
+	delete(r.hubs, webConn)
+	r.load[hub]--
+
+	userHubs := r.userHubs[webConn.UserId]
+	if userHubs[hub]--; userHubs[hub] == 0 {
+		delete(userHubs, hub)
+	}
+	if len(userHubs) == 0 {
+		delete(r.userHubs, webConn.UserId)
+	}
+
+	return hub
+}
+
+// hubsForUser returns the hubs that have connections for the user.
+func (r *hubRegistry) hubsForUser(userId string) []*Hub {
+	if r == nil {
+		return nil
+	}
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	hubs := make([]*Hub, 0, len(r.userHubs[userId]))
+	for hub := range r.userHubs[userId] {
+		hubs = append(hubs, hub)
+	}
+
+	return hubs
+}
+
+// users returns the users that have connections.
+func (r *hubRegistry) users() []string {
+	if r == nil {
+		return nil
+	}
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	userIds := make([]string, 0, len(r.userHubs))
+	for userId := range r.userHubs {
+		userIds = append(userIds, userId)
+	}
+
+	return userIds
+}
+
+// subscribeToPresence adds to the users whose status events are sent to the connection. It returns false without
+// subscribing to any of them if the connection would be subscribed to more than max users.
+func (r *hubRegistry) subscribeToPresence(webConn *WebConn, userIds []string, max int) bool {
+	if r == nil {
+		return true
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.hubs[webConn]; !ok {
+		// the connection has already been closed
+		return true
+	}
+
+	subscriptions := r.presenceSubscriptions[webConn]
+
+	count := len(subscriptions)
+	for _, userId := range userIds {
+		if !subscriptions[userId] {
+			count++
+		}
+	}
+	if count > max {
+		return false
+	}
+
+	if subscriptions == nil {
+		subscriptions = make(map[string]bool, len(userIds))
+		r.presenceSubscriptions[webConn] = subscriptions
+	}
+
+	for _, userId := range userIds {
+		subscriptions[userId] = true
+
+		subscribers, ok := r.presenceSubscribers[userId]
+		if !ok {
+			subscribers = make(map[*WebConn]bool)
+			r.presenceSubscribers[userId] = subscribers
+		}
+		subscribers[webConn] = true
+	}
+
+	return true
+}
+
+// unsubscribeFromPresence stops the status events of the given users from being sent to the connection, or of
+// every user if userIds is empty.
+func (r *hubRegistry) unsubscribeFromPresence(webConn *WebConn, userIds []string) {
+	if r == nil {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.removePresenceSubscriptions(webConn, userIds)
+}
+
+func (r *hubRegistry) removePresenceSubscriptions(webConn *WebConn, userIds []string) {
+	subscriptions, ok := r.presenceSubscriptions[webConn]
+	if !ok {
+		return
+	}
+
+	if len(userIds) == 0 {
+		for userId := range subscriptions {
+			userIds = append(userIds, userId)
+		}
+	}
+
+	for _, userId := range userIds {
+		if !subscriptions[userId] {
+			continue
+		}
+		delete(subscriptions, userId)
+
+		subscribers := r.presenceSubscribers[userId]
+		if delete(subscribers, webConn); len(subscribers) == 0 {
+			delete(r.presenceSubscribers, userId)
+		}
+	}
+
+	if len(subscriptions) == 0 {
+		delete(r.presenceSubscriptions, webConn)
+	}
+}
+
+// presenceSubscriptionCount returns the number of users whose presence the connection is subscribed to.
+func (r *hubRegistry) presenceSubscriptionCount(webConn *WebConn) int {
+	if r == nil {
+		return 0
+	}
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return len(r.presenceSubscriptions[webConn])
+}
+
+// broadcast queues the event on every hub with connections that it could be sent to. This holds the registry's
+// read lock so that connections can't move between hubs partway through.
+func (r *hubRegistry) broadcast(message *model.WebSocketEvent) {
+	if r == nil {
+		return
+	}
+
+	// done before the event is shared between hubs
+	message.PrecomputeJSON()
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if message.Broadcast.UserId != "" {
+		var subscribers map[*Hub][]*WebConn
+		if message.Event == model.WEBSOCKET_EVENT_STATUS_CHANGE {
+			subscribers = r.presenceSubscribersByHub(message.Broadcast.UserId)
+		}
+
+		for hub := range r.userHubs[message.Broadcast.UserId] {
+			hub.broadcastToSubscribers(message, subscribers[hub])
+			delete(subscribers, hub)
+		}
+
+		for hub, hubSubscribers := range subscribers {
+			hub.broadcastToSubscribers(message, hubSubscribers)
+		}
+	} else {
+		for _, hub := range r.all {
+			hub.Broadcast(message)
+		}
+	}
+}
+
+// presenceSubscribersByHub returns the connections that are subscribed to a user's presence, grouped by the hubs
+// that they belong to. It must be called with the lock held.
+func (r *hubRegistry) presenceSubscribersByHub(userId string) map[*Hub][]*WebConn {
+	subscribers := r.presenceSubscribers[userId]
+	if len(subscribers) == 0 {
+		return nil
+	}
+
+	byHub := make(map[*Hub][]*WebConn)
+	for webConn := range subscribers {
+		if hub, ok := r.hubs[webConn]; ok {
+			byHub[hub] = append(byHub[hub], webConn)
+		}
+	}
+
+	return byHub
+}
+
+// rebalance evens out the number of connections in each hub by asking the busiest hubs to move some of their
+// connections that haven't been sent anything since idleBefore to the quietest ones.
+func (r *hubRegistry) rebalance(idleBefore int64) {
+	if r == nil || len(r.all) < 2 {
+		return
+	}
+
+	r.lock.RLock()
+	load := make(map[*Hub]int, len(r.all))
+	for _, hub := range r.all {
+		load[hub] = r.load[hub]
+	}
+	r.lock.RUnlock()
+
+	moves := make(map[*Hub]map[*Hub]int)
+	for {
+		busiest, quietest := r.all[0], r.all[0]
+		for _, hub := range r.all[1:] {
+			if load[hub] > load[busiest] {
+				busiest = hub
+			}
+			if load[hub] < load[quietest] {
+				quietest = hub
+			}
+		}
+
+		count := (load[busiest] - load[quietest]) / 2
+		if count == 0 {
+			break
+		}
+
+		if moves[busiest] == nil {
+			moves[busiest] = make(map[*Hub]int)
+		}
+		moves[busiest][quietest] += count
+		load[busiest] -= count
+		load[quietest] += count
+	}
+
+	for hub, targets := range moves {
+		remaining := HUB_REBALANCE_MAX_MOVES
+		for target, count := range targets {
+			if count > remaining {
+				count = remaining
+			}
+			if count == 0 {
+				break
+			}
+			remaining -= count
+
+			select {
+			case hub.rebalance <- hubRebalance{target: target, count: count, idleBefore: idleBefore}:
+			case <-hub.stop:
+				return
+			}
+		}
+	}
+}
+
 type hubConnectionIndexIndexes struct {
 	connections         int
 	connectionsByUserId int
@@ -512,6 +1064,11 @@ func (i *hubConnectionIndex) Remove(wc *WebConn) {
 	delete(i.connectionIndexes, wc)
 }
 
+func (i *hubConnectionIndex) Has(wc *WebConn) bool {
+	_, ok := i.connectionIndexes[wc]
+	return ok
+}
+
 func (i *hubConnectionIndex) ForUser(id string) []*WebConn {
 	return i.connectionsByUserId[id]
 }
@@ -1,13 +1,18 @@
 package app
 
 import (
+	"math"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gorilla/websocket"
 	goi18n "github.com/nicksnyder/go-i18n/i18n"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/mattermost/mattermost-server/model"
@@ -58,3 +63,562 @@ func TestHubStopWithMultipleConnections(t *testing.T) {
 	registerDummyWebConn(t, th.App, s.Listener.Addr(), th.BasicUser.Id)
 	th.App.HubStop()
 }
+
+func TestHubStartUsesConfiguredHubCount(t *testing.T) {
+	th := Setup()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.ServiceSettings.WebsocketHubs = 3
+	})
+
+	th.App.HubStart()
+	assert.Len(t, th.App.Hubs, 3)
+	th.App.HubStop()
+}
+
+// newTestHubWebConn creates an authenticated connection with no websocket behind it, so the events that it's sent
+// can be read straight from its Send channel.
+func newTestHubWebConn(a *App, userId string) *WebConn {
+	wc := &WebConn{
+		App:          a,
+		Send:         make(chan model.WebSocketMessage, SEND_QUEUE_SIZE),
+		UserId:       userId,
+		endWritePump: make(chan struct{}, 2),
+		pumpFinished: make(chan struct{}, 1),
+	}
+
+	wc.SetSession(&model.Session{UserId: userId})
+	wc.SetSessionToken(model.NewId())
+	wc.SetSessionExpiresAt(model.GetMillis() + 60*60*1000)
+
+	return wc
+}
+
+func startTestHubs(a *App, count int) *hubRegistry {
+	hubs := make([]*Hub, count)
+	for i := range hubs {
+		hubs[i] = a.NewWebHub()
+		hubs[i].connectionIndex = i
+	}
+
+	registry := newHubRegistry(hubs)
+	for _, hub := range hubs {
+		hub.registry = registry
+		hub.Start()
+	}
+
+	return registry
+}
+
+func registerTestHubWebConn(registry *hubRegistry, wc *WebConn) {
+	registry.add(wc).Register(wc)
+}
+
+func unregisterTestHubWebConn(registry *hubRegistry, wc *WebConn) {
+	atomic.StoreInt32(&wc.unregistered, 1)
+	if hub, _ := registry.remove(wc); hub != nil {
+		hub.Unregister(wc)
+	}
+}
+
+func hubConnectionCounts(registry *hubRegistry) []int64 {
+	counts := make([]int64, len(registry.all))
+	for i, hub := range registry.all {
+		counts[i] = atomic.LoadInt64(&hub.connectionCount)
+	}
+	return counts
+}
+
+// waitForBalancedHubs waits for every hub to have total/len(hubs) connections, give or take one.
+func waitForBalancedHubs(t *testing.T, registry *hubRegistry, total int64) {
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		counts := hubConnectionCounts(registry)
+
+		balanced := true
+		var sum int64
+		for _, count := range counts {
+			sum += count
+			if count < total/int64(len(counts)) || count > total/int64(len(counts))+1 {
+				balanced = false
+			}
+		}
+
+		if balanced && sum == total {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			require.FailNow(t, "timed out waiting for the hubs to balance", "counts=%v", counts)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func drainTestHubWebConn(wc *WebConn) {
+	for {
+		select {
+		case <-wc.Send:
+		default:
+			return
+		}
+	}
+}
+
+func stopTestHubs(t *testing.T, registry *hubRegistry, conns []*WebConn) {
+	for _, wc := range conns {
+		unregisterTestHubWebConn(registry, wc)
+	}
+
+	waitForBalancedHubs(t, registry, 0)
+
+	for _, hub := range registry.all {
+		hub.Stop()
+	}
+}
+
+func TestHubDistributesConnectionsEvenly(t *testing.T) {
+	a := &App{}
+	registry := startTestHubs(a, 8)
+
+	// A hot user with many connections among lots of users with a couple each
+	hotUserId := model.NewId()
+	var conns []*WebConn
+	var hotConns []*WebConn
+	for i := 0; i < 200; i++ {
+		hot := newTestHubWebConn(a, hotUserId)
+		hotConns = append(hotConns, hot)
+
+		userId := model.NewId()
+		conns = append(conns, hot, newTestHubWebConn(a, userId), newTestHubWebConn(a, userId))
+	}
+
+	for _, wc := range conns {
+		registerTestHubWebConn(registry, wc)
+	}
+	defer func() {
+		stopTestHubs(t, registry, conns)
+	}()
+
+	waitForBalancedHubs(t, registry, 600)
+
+	t.Run("events for a user reach their connections in every hub", func(t *testing.T) {
+		registry.broadcast(model.NewWebSocketEvent(model.WEBSOCKET_EVENT_TYPING, "", "", hotUserId, nil))
+
+		for _, wc := range hotConns {
+			select {
+			case msg := <-wc.Send:
+				require.Equal(t, model.WEBSOCKET_EVENT_HELLO, msg.EventType())
+			case <-time.After(5 * time.Second):
+				require.FailNow(t, "didn't receive hello")
+			}
+
+			select {
+			case msg := <-wc.Send:
+				require.Equal(t, model.WEBSOCKET_EVENT_TYPING, msg.EventType())
+			case <-time.After(5 * time.Second):
+				require.FailNow(t, "didn't receive event")
+			}
+		}
+	})
+
+	t.Run("rebalancing", func(t *testing.T) {
+		// Connections with messages waiting to be written aren't idle
+		for _, wc := range conns {
+			drainTestHubWebConn(wc)
+		}
+
+		// Emptying two of the hubs leaves them idle while the others are busy
+		var remaining []*WebConn
+		for _, wc := range conns {
+			if hub := registry.hubs[wc]; hub == registry.all[0] || hub == registry.all[1] {
+				unregisterTestHubWebConn(registry, wc)
+			} else {
+				remaining = append(remaining, wc)
+			}
+		}
+		conns = remaining
+
+		counts := hubConnectionCounts(registry)
+		require.Condition(t, func() bool { return counts[0] <= 75 && counts[1] <= 75 }, "counts=%v", counts)
+
+		registry.rebalance(model.GetMillis())
+		waitForBalancedHubs(t, registry, int64(len(conns)))
+	})
+}
+
+func TestHubRebalanceKeepsEventsInOrder(t *testing.T) {
+	a := &App{}
+	registry := startTestHubs(a, 2)
+
+	userId := model.NewId()
+	const eventCount = 2000
+
+	// The connection has room for every event so that it's never closed for falling behind
+	wc := newTestHubWebConn(a, userId)
+	wc.Send = make(chan model.WebSocketMessage, eventCount+1)
+	registerTestHubWebConn(registry, wc)
+	defer func() {
+		stopTestHubs(t, registry, []*WebConn{wc})
+	}()
+
+	waitForBalancedHubs(t, registry, 1)
+
+	received := make(chan []int, 1)
+	go func() {
+		var sequence []int
+		for len(sequence) < eventCount {
+			select {
+			case msg, ok := <-wc.Send:
+				if !ok {
+					received <- sequence
+					return
+				}
+
+				if evt, ok := msg.(*model.WebSocketEvent); ok && evt.Event == model.WEBSOCKET_EVENT_TYPING {
+					sequence = append(sequence, evt.Data["sequence"].(int))
+				}
+			case <-time.After(10 * time.Second):
+				received <- sequence
+				return
+			}
+		}
+		received <- sequence
+	}()
+
+	moves := 0
+	for i := 0; i < eventCount; i++ {
+		evt := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_TYPING, "", "", userId, nil)
+		evt.Add("sequence", i)
+		registry.broadcast(evt)
+
+		if i%100 == 50 {
+			// Only connections that are keeping up can be moved, although there may still be events for it queued
+			// on its hub
+			deadline := time.Now().Add(5 * time.Second)
+			for len(wc.Send) > 0 && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+
+			registry.lock.RLock()
+			from := registry.hubs[wc]
+			registry.lock.RUnlock()
+
+			to := registry.all[0]
+			if from == to {
+				to = registry.all[1]
+			}
+
+			from.rebalance <- hubRebalance{target: to, count: 1, idleBefore: math.MaxInt64}
+
+			registry.lock.RLock()
+			if registry.hubs[wc] == to {
+				moves++
+			}
+			registry.lock.RUnlock()
+		}
+	}
+
+	sequence := <-received
+	require.Len(t, sequence, eventCount)
+	for i, value := range sequence {
+		require.Equal(t, i, value, "events were delivered out of order")
+	}
+
+	assert.NotZero(t, moves, "the connection should've been moved between hubs")
+	for _, hub := range registry.all {
+		assert.Zero(t, hub.DroppedEvents())
+	}
+}
+
+func TestHubBroadcastDropsEventsWhenFull(t *testing.T) {
+	hub := (&App{}).NewWebHub()
+
+	for i := 0; i < BROADCAST_QUEUE_SIZE+10; i++ {
+		hub.Broadcast(model.NewWebSocketEvent(model.WEBSOCKET_EVENT_TYPING, "", "", model.NewId(), nil))
+	}
+
+	assert.Equal(t, int64(10), hub.DroppedEvents())
+	assert.Len(t, hub.broadcast, BROADCAST_QUEUE_SIZE)
+}
+
+// newSlowClientTestHubWebConn registers a connection whose send queue only has room for queueSize events.
+func newSlowClientTestHubWebConn(t *testing.T, policy string, maxDrops int, queueSize int) (*hubRegistry, *WebConn) {
+	cfg := &model.Config{}
+	cfg.SetDefaults()
+	*cfg.ServiceSettings.WebsocketSlowClientPolicy = policy
+	*cfg.ServiceSettings.WebsocketSlowClientMaxDrops = maxDrops
+
+	a := &App{}
+	a.config.Store(cfg)
+	registry := startTestHubs(a, 1)
+
+	wc := newTestHubWebConn(a, model.NewId())
+	wc.Send = make(chan model.WebSocketMessage, queueSize)
+	registerTestHubWebConn(registry, wc)
+	waitForBalancedHubs(t, registry, 1)
+
+	msg := <-wc.Send
+	require.Equal(t, model.WEBSOCKET_EVENT_HELLO, msg.EventType())
+
+	return registry, wc
+}
+
+func broadcastSlowClientTestEvent(registry *hubRegistry, wc *WebConn, sequence int) {
+	evt := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_TYPING, "", "", wc.UserId, nil)
+	evt.Add("sequence", sequence)
+	registry.broadcast(evt)
+}
+
+func waitForDroppedEvents(t *testing.T, wc *WebConn, count int64) {
+	deadline := time.Now().Add(10 * time.Second)
+	for wc.DroppedEvents() < count {
+		if time.Now().After(deadline) {
+			require.FailNow(t, "timed out waiting for events to be dropped", "dropped=%v", wc.DroppedEvents())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func receiveSlowClientTestEvent(t *testing.T, wc *WebConn) *model.WebSocketEvent {
+	select {
+	case msg, ok := <-wc.Send:
+		require.True(t, ok, "the connection shouldn't have been closed")
+		return msg.(*model.WebSocketEvent)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "didn't receive event")
+		return nil
+	}
+}
+
+func TestHubSlowClientPolicy(t *testing.T) {
+	t.Run("drop tells the client that it missed events", func(t *testing.T) {
+		registry, wc := newSlowClientTestHubWebConn(t, model.WEBSOCKET_SLOW_CLIENT_POLICY_DROP, 1, 4)
+		defer func() {
+			stopTestHubs(t, registry, []*WebConn{wc})
+		}()
+
+		// The client stops reading, so only the first four events fit
+		for i := 0; i < 10; i++ {
+			broadcastSlowClientTestEvent(registry, wc, i)
+		}
+		waitForDroppedEvents(t, wc, 6)
+
+		for i := 0; i < 4; i++ {
+			assert.Equal(t, i, receiveSlowClientTestEvent(t, wc).Data["sequence"])
+		}
+
+		broadcastSlowClientTestEvent(registry, wc, 10)
+		assert.Equal(t, model.WEBSOCKET_EVENT_MISSED_EVENTS, receiveSlowClientTestEvent(t, wc).Event)
+		assert.Equal(t, 10, receiveSlowClientTestEvent(t, wc).Data["sequence"])
+
+		broadcastSlowClientTestEvent(registry, wc, 11)
+		assert.Equal(t, 11, receiveSlowClientTestEvent(t, wc).Data["sequence"], "the client should only be told once")
+
+		assert.Equal(t, int64(6), wc.DroppedEvents())
+		assert.Equal(t, []int64{1}, hubConnectionCounts(registry))
+	})
+
+	t.Run("disconnect closes the connection after too many drops in a row", func(t *testing.T) {
+		registry, wc := newSlowClientTestHubWebConn(t, model.WEBSOCKET_SLOW_CLIENT_POLICY_DISCONNECT, 3, 4)
+		defer func() {
+			stopTestHubs(t, registry, []*WebConn{wc})
+		}()
+
+		for i := 0; i < 6; i++ {
+			broadcastSlowClientTestEvent(registry, wc, i)
+		}
+		waitForDroppedEvents(t, wc, 2)
+		assert.Equal(t, []int64{1}, hubConnectionCounts(registry))
+
+		// Receiving two events makes room for the missed events event and one more, which resets the count
+		assert.Equal(t, 0, receiveSlowClientTestEvent(t, wc).Data["sequence"])
+		assert.Equal(t, 1, receiveSlowClientTestEvent(t, wc).Data["sequence"])
+		broadcastSlowClientTestEvent(registry, wc, 6)
+		broadcastSlowClientTestEvent(registry, wc, 7)
+		broadcastSlowClientTestEvent(registry, wc, 8)
+		waitForDroppedEvents(t, wc, 4)
+		assert.Equal(t, []int64{1}, hubConnectionCounts(registry))
+
+		broadcastSlowClientTestEvent(registry, wc, 9)
+		waitForDroppedEvents(t, wc, 5)
+
+		var events []string
+		for msg := range wc.Send {
+			evt := msg.(*model.WebSocketEvent)
+			if evt.Event == model.WEBSOCKET_EVENT_MISSED_EVENTS {
+				events = append(events, evt.Event)
+			} else {
+				events = append(events, strconv.Itoa(evt.Data["sequence"].(int)))
+			}
+		}
+		assert.Equal(t, []string{"2", "3", model.WEBSOCKET_EVENT_MISSED_EVENTS, "6"}, events)
+		assert.Equal(t, []int64{0}, hubConnectionCounts(registry))
+	})
+
+	t.Run("a slow reader receives the events that it's sent in order", func(t *testing.T) {
+		registry, wc := newSlowClientTestHubWebConn(t, model.WEBSOCKET_SLOW_CLIENT_POLICY_DROP, 1, 8)
+		defer func() {
+			stopTestHubs(t, registry, []*WebConn{wc})
+		}()
+
+		const eventCount = 500
+
+		received := make(chan []int, 1)
+		go func() {
+			var sequence []int
+			for {
+				evt := receiveSlowClientTestEvent(t, wc)
+				if evt.Event == model.WEBSOCKET_EVENT_MISSED_EVENTS {
+					sequence = append(sequence, -1)
+				} else if evt.Data["sequence"] == eventCount {
+					received <- sequence
+					return
+				} else {
+					sequence = append(sequence, evt.Data["sequence"].(int))
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}()
+
+		for i := 0; i < eventCount; i++ {
+			broadcastSlowClientTestEvent(registry, wc, i)
+		}
+
+		// The final event is sent until it gets through
+		var sequence []int
+		for sequence == nil {
+			broadcastSlowClientTestEvent(registry, wc, eventCount)
+			select {
+			case sequence = <-received:
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+
+		require.NotZero(t, wc.DroppedEvents(), "the reader should've fallen behind")
+
+		last, delivered, missed := -1, 0, false
+		for _, value := range sequence {
+			if value == -1 {
+				missed = true
+				continue
+			}
+			require.True(t, value > last, "events were delivered out of order")
+			last = value
+			delivered++
+		}
+		assert.True(t, missed, "the reader should've been told that it missed events")
+		// Copies of the final event may have been dropped too
+		assert.True(t, int64(delivered)+wc.DroppedEvents() >= eventCount, "every event should've been delivered or dropped")
+	})
+}
+
+func TestHubPresenceSubscriptions(t *testing.T) {
+	a := &App{}
+
+	cfg := &model.Config{}
+	cfg.SetDefaults()
+	*cfg.ServiceSettings.EnablePresenceSubscriptions = true
+	*cfg.ServiceSettings.MaxPresenceSubscriptionsPerConnection = 2
+	a.config.Store(cfg)
+
+	registry := startTestHubs(a, 2)
+	a.hubRegistry = registry
+
+	userId := model.NewId()
+	watched := newTestHubWebConn(a, userId)
+	subscriber := newTestHubWebConn(a, model.NewId())
+	other := newTestHubWebConn(a, model.NewId())
+
+	// Cached statuses are looked up without a database
+	a.AddStatusCacheSkipClusterSend(&model.Status{UserId: userId, Status: model.STATUS_OFFLINE})
+	extraUserIds := []string{model.NewId(), model.NewId(), model.NewId()}
+	for _, extraUserId := range extraUserIds {
+		a.AddStatusCacheSkipClusterSend(&model.Status{UserId: extraUserId, Status: model.STATUS_OFFLINE})
+	}
+
+	conns := []*WebConn{watched, subscriber, other}
+	for _, wc := range conns {
+		registerTestHubWebConn(registry, wc)
+	}
+	defer func() {
+		stopTestHubs(t, registry, conns)
+	}()
+
+	receive := func(wc *WebConn) model.WebSocketMessage {
+		select {
+		case msg := <-wc.Send:
+			return msg
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "didn't receive event")
+			return nil
+		}
+	}
+
+	for _, wc := range conns {
+		require.Equal(t, model.WEBSOCKET_EVENT_HELLO, receive(wc).EventType())
+	}
+
+	statuses, err := a.SubscribeToPresence(subscriber, []string{userId})
+	require.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{userId: model.STATUS_OFFLINE}, statuses)
+
+	t.Run("status events are only sent to subscribers", func(t *testing.T) {
+		a.BroadcastStatus(&model.Status{UserId: userId, Status: model.STATUS_ONLINE})
+
+		for _, wc := range []*WebConn{watched, subscriber} {
+			msg := receive(wc).(*model.WebSocketEvent)
+			assert.Equal(t, model.WEBSOCKET_EVENT_STATUS_CHANGE, msg.Event)
+			assert.Equal(t, userId, msg.Data["user_id"])
+		}
+
+		// Anything sent to the other connection after the status event would only arrive after it
+		registry.broadcast(model.NewWebSocketEvent(model.WEBSOCKET_EVENT_TYPING, "", "", other.UserId, nil))
+		assert.Equal(t, model.WEBSOCKET_EVENT_TYPING, receive(other).EventType())
+	})
+
+	t.Run("subscriptions are capped", func(t *testing.T) {
+		_, err := a.SubscribeToPresence(subscriber, extraUserIds[:2])
+		require.NotNil(t, err)
+		assert.Equal(t, "app.status.subscribe.too_many.app_error", err.Id)
+		assert.Equal(t, 1, registry.presenceSubscriptionCount(subscriber))
+
+		// Users that are already subscribed to don't count twice
+		_, err = a.SubscribeToPresence(subscriber, []string{userId, extraUserIds[2]})
+		require.Nil(t, err)
+		assert.Equal(t, 2, registry.presenceSubscriptionCount(subscriber))
+	})
+
+	t.Run("unsubscribing", func(t *testing.T) {
+		a.UnsubscribeFromPresence(subscriber, []string{userId})
+		assert.Equal(t, 1, registry.presenceSubscriptionCount(subscriber))
+
+		a.BroadcastStatus(&model.Status{UserId: userId, Status: model.STATUS_AWAY})
+		assert.Equal(t, model.WEBSOCKET_EVENT_STATUS_CHANGE, receive(watched).EventType())
+
+		registry.broadcast(model.NewWebSocketEvent(model.WEBSOCKET_EVENT_TYPING, "", "", subscriber.UserId, nil))
+		assert.Equal(t, model.WEBSOCKET_EVENT_TYPING, receive(subscriber).EventType())
+	})
+
+	t.Run("disconnecting drops the subscriptions", func(t *testing.T) {
+		_, err := a.SubscribeToPresence(other, []string{userId})
+		require.Nil(t, err)
+
+		unregisterTestHubWebConn(registry, other)
+		conns = []*WebConn{watched, subscriber}
+
+		assert.Equal(t, 0, registry.presenceSubscriptionCount(other))
+		assert.NotContains(t, registry.presenceSubscribers[userId], other)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		disabled := cfg.Clone()
+		*disabled.ServiceSettings.EnablePresenceSubscriptions = false
+		a.config.Store(disabled)
+
+		_, err := a.SubscribeToPresence(subscriber, []string{userId})
+		require.NotNil(t, err)
+		assert.Equal(t, "app.status.subscribe.disabled.app_error", err.Id)
+	})
+}
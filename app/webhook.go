@@ -4,8 +4,8 @@
 package app
 
 import (
+	"bytes"
 	"fmt"
-	"io"
 	"net/http"
 	"regexp"
 	"strings"
@@ -66,8 +66,13 @@ func (a *App) handleWebhookEvents(post *model.Post, team *model.Team, channel *m
 	}
 
 	for _, hook := range relevantHooks {
+		token := hook.Token
+		if hook.DisableLegacyToken {
+			token = ""
+		}
+
 		payload := &model.OutgoingWebhookPayload{
-			Token:       hook.Token,
+			Token:       token,
 			TeamId:      hook.TeamId,
 			TeamDomain:  team.Name,
 			ChannelId:   post.ChannelId,
@@ -80,35 +85,39 @@ func (a *App) handleWebhookEvents(post *model.Post, team *model.Team, channel *m
 			TriggerWord: triggerWord,
 			FileIds:     strings.Join(post.FileIds, ","),
 		}
-		a.Go(func(hook *model.OutgoingWebhook) func() {
-			return func() {
-				a.TriggerWebhook(payload, hook, post, channel)
-			}
-		}(hook))
+
+		// the hook is triggered from here, rather than in the background, so that its requests are queued in the
+		// order that the posts were made
+		a.TriggerWebhook(payload, hook, post, channel)
 	}
 
 	return nil
 }
 
 func (a *App) TriggerWebhook(payload *model.OutgoingWebhookPayload, hook *model.OutgoingWebhook, post *model.Post, channel *model.Channel) {
-	var body io.Reader
+	var body []byte
 	var contentType string
 	if hook.ContentType == "application/json" {
-		body = strings.NewReader(payload.ToJSON())
+		body = []byte(payload.ToJSON())
 		contentType = "application/json"
 	} else {
-		body = strings.NewReader(payload.ToFormValues())
+		body = []byte(payload.ToFormValues())
 		contentType = "application/x-www-form-urlencoded"
 	}
 
 	for _, url := range hook.CallbackURLs {
-		a.Go(func(url string) func() {
+		// each URL gets the hook's events in order, so a request isn't sent until the previous one has finished
+		a.outgoingWebhookQueues.queue(hook.Id+" "+url, func(url string) func() {
 			return func() {
-				req, _ := http.NewRequest("POST", url, body)
+				req, _ := http.NewRequest("POST", url, bytes.NewReader(body))
 				req.Header.Set("Content-Type", contentType)
 				req.Header.Set("Accept", "application/json")
+				model.SetIntegrationSignatureHeaders(req.Header, hook.SigningAlgorithm, hook.SigningSecret, model.GetMillis(), body)
+				if post.RequestId != "" {
+					req.Header.Set(model.HEADER_REQUEST_ID, post.RequestId)
+				}
 				if resp, err := a.HTTPClient(false).Do(req); err != nil {
-					mlog.Error(fmt.Sprintf("Event POST failed, err=%s", err.Error()))
+					mlog.Error(fmt.Sprintf("Event POST failed, err=%s", err.Error()), mlog.String("request_id", post.RequestId))
 				} else {
 					defer consumeAndClose(resp)
 
@@ -140,7 +149,7 @@ func (a *App) TriggerWebhook(payload *model.OutgoingWebhookPayload, hook *model.
 					}
 				}
 			}
-		}(url))
+		}(url), a.Go)
 	}
 }
 
@@ -225,6 +234,36 @@ func SplitWebhookPost(post *model.Post, maxPostSize int) ([]*model.Post, *model.
 }
 
 func (a *App) CreateWebhookPost(userId string, channel *model.Channel, text, overrideUsername, overrideIconUrl string, props model.StringInterface, postType string, postRootId string) (*model.Post, *model.AppError) {
+	return a.createWebhookPost(userId, channel, text, overrideUsername, overrideIconUrl, props, postType, postRootId, "")
+}
+
+// createWebhookPost creates a post like CreateWebhookPost, marking it as created by the incoming webhook with the
+// given id, if there is one, so that the webhook can update and delete it later.
+func (a *App) createWebhookPost(userId string, channel *model.Channel, text, overrideUsername, overrideIconUrl string, props model.StringInterface, postType string, postRootId string, webhookId string) (*model.Post, *model.AppError) {
+	post, err := a.buildWebhookPost(userId, channel, text, overrideUsername, overrideIconUrl, props, postType, postRootId, webhookId)
+	if err != nil {
+		return nil, err
+	}
+
+	if metrics := a.Metrics; metrics != nil {
+		metrics.IncrementWebhookPost()
+	}
+
+	splits, err := SplitWebhookPost(post, a.MaxPostSize())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, split := range splits {
+		if _, err := a.CreatePostMissingChannel(split, false); err != nil {
+			return nil, model.NewAppError("CreateWebhookPost", "api.post.create_webhook_post.creating.app_error", nil, "err="+err.Message, http.StatusInternalServerError)
+		}
+	}
+
+	return splits[0], nil
+}
+
+func (a *App) buildWebhookPost(userId string, channel *model.Channel, text, overrideUsername, overrideIconUrl string, props model.StringInterface, postType string, postRootId string, webhookId string) (*model.Post, *model.AppError) {
 	// parse links into Markdown format
 	linkWithTextRegex := regexp.MustCompile(`<([^\n<\|>]+)\|([^\n>]+)>`)
 	text = linkWithTextRegex.ReplaceAllString(text, "[${2}](${1})")
@@ -237,10 +276,6 @@ func (a *App) CreateWebhookPost(userId string, channel *model.Channel, text, ove
 		return nil, err
 	}
 
-	if metrics := a.Metrics; metrics != nil {
-		metrics.IncrementWebhookPost()
-	}
-
 	if a.Config().ServiceSettings.EnablePostUsernameOverride {
 		if len(overrideUsername) != 0 {
 			post.AddProp("override_username", overrideUsername)
@@ -261,24 +296,57 @@ func (a *App) CreateWebhookPost(userId string, channel *model.Channel, text, ove
 				if attachments, success := val.([]*model.SlackAttachment); success {
 					parseSlackAttachment(post, attachments)
 				}
-			} else if key != "override_icon_url" && key != "override_username" && key != "from_webhook" {
+			} else if key != "override_icon_url" && key != "override_username" && key != "from_webhook" && key != model.POST_PROPS_WEBHOOK_ID {
 				post.AddProp(key, val)
 			}
 		}
 	}
 
-	splits, err := SplitWebhookPost(post, a.MaxPostSize())
+	if webhookId != "" {
+		post.AddProp(model.POST_PROPS_WEBHOOK_ID, webhookId)
+	}
+
+	return post, nil
+}
+
+// getIncomingWebhookPost returns the post with the given id if it was created by the incoming webhook, since no
+// other posts can be changed through it, even ones by the user who created the webhook.
+func (a *App) getIncomingWebhookPost(hook *model.IncomingWebhook, postId string) (*model.Post, *model.AppError) {
+	result := <-a.Srv.Store.Post().GetSingle(postId)
+	if result.Err != nil {
+		return nil, model.NewAppError("HandleIncomingWebhook", "web.incoming_webhook.post.app_error", nil, "post_id="+postId+", err="+result.Err.Message, http.StatusBadRequest)
+	}
+	post := result.Data.(*model.Post)
+
+	if webhookId, _ := post.Props[model.POST_PROPS_WEBHOOK_ID].(string); webhookId != hook.Id || post.UserId != hook.UserId {
+		return nil, model.NewAppError("HandleIncomingWebhook", "web.incoming_webhook.post_not_owned.app_error", nil, "post_id="+postId, http.StatusForbidden)
+	}
+
+	return post, nil
+}
+
+// updateIncomingWebhookPost replaces the message and props of a post created by the incoming webhook as an edit of
+// it. Unlike when creating a post, a message that's too long for one post is an error instead of being split up.
+func (a *App) updateIncomingWebhookPost(hook *model.IncomingWebhook, oldPost *model.Post, channel *model.Channel, text, overrideUsername, overrideIconUrl string, props model.StringInterface, postType string) (*model.Post, *model.AppError) {
+	post, err := a.buildWebhookPost(hook.UserId, channel, text, overrideUsername, overrideIconUrl, props, postType, oldPost.RootId, hook.Id)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, split := range splits {
-		if _, err := a.CreatePostMissingChannel(split, false); err != nil {
-			return nil, model.NewAppError("CreateWebhookPost", "api.post.create_webhook_post.creating.app_error", nil, "err="+err.Message, http.StatusInternalServerError)
-		}
+	splits, err := SplitWebhookPost(post, a.MaxPostSize())
+	if err != nil {
+		return nil, err
+	}
+	if len(splits) > 1 {
+		return nil, model.NewAppError("HandleIncomingWebhook", "web.incoming_webhook.update_post_length.app_error", map[string]interface{}{"Max": a.MaxPostSize()}, "post_id="+oldPost.Id, http.StatusBadRequest)
 	}
 
-	return splits[0], nil
+	updated := &model.Post{}
+	*updated = *oldPost
+	updated.Message = splits[0].Message
+	updated.Props = splits[0].Props
+
+	return a.UpdatePost(updated, false)
 }
 
 func (a *App) CreateIncomingWebhookForChannel(creatorId string, channel *model.Channel, hook *model.IncomingWebhook) (*model.IncomingWebhook, *model.AppError) {
@@ -475,6 +543,7 @@ func (a *App) UpdateOutgoingWebhook(oldHook, updatedHook *model.OutgoingWebhook)
 	}
 
 	updatedHook.CreatorId = oldHook.CreatorId
+	updatedHook.SigningSecret = oldHook.SigningSecret
 	updatedHook.CreateAt = oldHook.CreateAt
 	updatedHook.DeleteAt = oldHook.DeleteAt
 	updatedHook.TeamId = oldHook.TeamId
@@ -561,20 +630,41 @@ func (a *App) RegenOutgoingWebhookToken(hook *model.OutgoingWebhook) (*model.Out
 	}
 }
 
-func (a *App) HandleIncomingWebhook(hookId string, req *model.IncomingWebhookRequest) *model.AppError {
+// RegenOutgoingWebhookSigningSecret replaces the secret that an outgoing webhook's requests are signed with.
+func (a *App) RegenOutgoingWebhookSigningSecret(hook *model.OutgoingWebhook) (*model.OutgoingWebhook, *model.AppError) {
+	if !a.Config().ServiceSettings.EnableOutgoingWebhooks {
+		return nil, model.NewAppError("RegenOutgoingWebhookSigningSecret", "api.outgoing_webhook.disabled.app_error", nil, "", http.StatusNotImplemented)
+	}
+
+	hook.SigningSecret = model.NewRandomString(model.INTEGRATION_SIGNING_SECRET_LENGTH)
+
+	if result := <-a.Srv.Store.Webhook().UpdateOutgoing(hook); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return result.Data.(*model.OutgoingWebhook), nil
+	}
+}
+
+// HandleIncomingWebhook creates, updates or deletes a post as requested by an incoming webhook, returning the post.
+// Updating and deleting posts are requests like creating them, so they're rate limited all the same.
+func (a *App) HandleIncomingWebhook(hookId string, req *model.IncomingWebhookRequest) (*model.Post, *model.AppError) {
 	if !a.Config().ServiceSettings.EnableIncomingWebhooks {
-		return model.NewAppError("HandleIncomingWebhook", "web.incoming_webhook.disabled.app_error", nil, "", http.StatusNotImplemented)
+		return nil, model.NewAppError("HandleIncomingWebhook", "web.incoming_webhook.disabled.app_error", nil, "", http.StatusNotImplemented)
 	}
 
 	hchan := a.Srv.Store.Webhook().GetIncoming(hookId, true)
 
 	if req == nil {
-		return model.NewAppError("HandleIncomingWebhook", "web.incoming_webhook.parse.app_error", nil, "", http.StatusBadRequest)
+		return nil, model.NewAppError("HandleIncomingWebhook", "web.incoming_webhook.parse.app_error", nil, "", http.StatusBadRequest)
 	}
 
 	text := req.Text
-	if len(text) == 0 && req.Attachments == nil {
-		return model.NewAppError("HandleIncomingWebhook", "web.incoming_webhook.text.app_error", nil, "", http.StatusBadRequest)
+	if len(strings.TrimSpace(text)) == 0 && len(req.Attachments) == 0 && req.DeletePostId == "" {
+		return nil, model.NewAppError("HandleIncomingWebhook", "web.incoming_webhook.text.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if req.UpdatePostId != "" && req.DeletePostId != "" {
+		return nil, model.NewAppError("HandleIncomingWebhook", "web.incoming_webhook.update_and_delete.app_error", nil, "", http.StatusBadRequest)
 	}
 
 	channelName := req.ChannelName
@@ -582,11 +672,30 @@ func (a *App) HandleIncomingWebhook(hookId string, req *model.IncomingWebhookReq
 
 	var hook *model.IncomingWebhook
 	if result := <-hchan; result.Err != nil {
-		return model.NewAppError("HandleIncomingWebhook", "web.incoming_webhook.invalid.app_error", nil, "err="+result.Err.Message, http.StatusBadRequest)
+		return nil, model.NewAppError("HandleIncomingWebhook", "web.incoming_webhook.invalid.app_error", nil, "err="+result.Err.Message, http.StatusBadRequest)
 	} else {
 		hook = result.Data.(*model.IncomingWebhook)
 	}
 
+	// The post that's updated or deleted is looked up before anything else so that a webhook can't find out about
+	// the channels of posts that it didn't create
+	if req.DeletePostId != "" {
+		post, err := a.getIncomingWebhookPost(hook, req.DeletePostId)
+		if err != nil {
+			return nil, err
+		}
+
+		return a.DeletePost(post.Id)
+	}
+
+	var oldPost *model.Post
+	if req.UpdatePostId != "" {
+		var err *model.AppError
+		if oldPost, err = a.getIncomingWebhookPost(hook, req.UpdatePostId); err != nil {
+			return nil, err
+		}
+	}
+
 	if len(req.Props) == 0 {
 		req.Props = make(model.StringInterface)
 	}
@@ -604,13 +713,15 @@ func (a *App) HandleIncomingWebhook(hookId string, req *model.IncomingWebhookReq
 	var channel *model.Channel
 	var cchan store.StoreChannel
 
-	if len(channelName) != 0 {
+	if oldPost != nil {
+		cchan = a.Srv.Store.Channel().Get(oldPost.ChannelId, true)
+	} else if len(channelName) != 0 {
 		if channelName[0] == '@' {
 			if result := <-a.Srv.Store.User().GetByUsername(channelName[1:]); result.Err != nil {
-				return model.NewAppError("HandleIncomingWebhook", "web.incoming_webhook.user.app_error", nil, "err="+result.Err.Message, http.StatusBadRequest)
+				return nil, model.NewAppError("HandleIncomingWebhook", "web.incoming_webhook.user.app_error", nil, "err="+result.Err.Message, http.StatusBadRequest)
 			} else {
 				if ch, err := a.GetDirectChannel(hook.UserId, result.Data.(*model.User).Id); err != nil {
-					return err
+					return nil, err
 				} else {
 					channel = ch
 				}
@@ -627,23 +738,27 @@ func (a *App) HandleIncomingWebhook(hookId string, req *model.IncomingWebhookReq
 	if channel == nil {
 		result := <-cchan
 		if result.Err != nil {
-			return model.NewAppError("HandleIncomingWebhook", "web.incoming_webhook.channel.app_error", nil, "err="+result.Err.Message, result.Err.StatusCode)
+			return nil, model.NewAppError("HandleIncomingWebhook", "web.incoming_webhook.channel.app_error", nil, "err="+result.Err.Message, result.Err.StatusCode)
 		} else {
 			channel = result.Data.(*model.Channel)
 		}
 	}
 
 	if hook.ChannelLocked && hook.ChannelId != channel.Id {
-		return model.NewAppError("HandleIncomingWebhook", "web.incoming_webhook.channel_locked.app_error", nil, "", http.StatusForbidden)
+		return nil, model.NewAppError("HandleIncomingWebhook", "web.incoming_webhook.channel_locked.app_error", nil, "", http.StatusForbidden)
+	}
+
+	if !hook.IsChannelAllowed(channel.Id) {
+		return nil, model.NewAppError("HandleIncomingWebhook", "web.incoming_webhook.channel_not_allowed.app_error", nil, "channel_id="+channel.Id, http.StatusForbidden)
 	}
 
 	if a.License() != nil && *a.Config().TeamSettings.ExperimentalTownSquareIsReadOnly &&
 		channel.Name == model.DEFAULT_CHANNEL {
-		return model.NewAppError("HandleIncomingWebhook", "api.post.create_post.town_square_read_only", nil, "", http.StatusForbidden)
+		return nil, model.NewAppError("HandleIncomingWebhook", "api.post.create_post.town_square_read_only", nil, "", http.StatusForbidden)
 	}
 
 	if channel.Type != model.CHANNEL_OPEN && !a.HasPermissionToChannel(hook.UserId, channel.Id, model.PERMISSION_READ_CHANNEL) {
-		return model.NewAppError("HandleIncomingWebhook", "web.incoming_webhook.permissions.app_error", nil, "", http.StatusForbidden)
+		return nil, model.NewAppError("HandleIncomingWebhook", "web.incoming_webhook.permissions.app_error", nil, "", http.StatusForbidden)
 	}
 
 	overrideUsername := hook.Username
@@ -656,8 +771,11 @@ func (a *App) HandleIncomingWebhook(hookId string, req *model.IncomingWebhookReq
 		overrideIconUrl = req.IconURL
 	}
 
-	_, err := a.CreateWebhookPost(hook.UserId, channel, text, overrideUsername, overrideIconUrl, req.Props, webhookType, "")
-	return err
+	if oldPost != nil {
+		return a.updateIncomingWebhookPost(hook, oldPost, channel, text, overrideUsername, overrideIconUrl, req.Props, webhookType)
+	}
+
+	return a.createWebhookPost(hook.UserId, channel, text, overrideUsername, overrideIconUrl, req.Props, webhookType, "", hook.Id)
 }
 
 func (a *App) CreateCommandWebhook(commandId string, args *model.CommandArgs) (*model.CommandWebhook, *model.AppError) {
@@ -377,6 +377,110 @@ Date:   Thu Mar 1 19:46:48 2018 +0300
 	assert.Equal(t, expectedText, post.Message)
 }
 
+func TestHandleIncomingWebhookUpdateAndDeletePost(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { cfg.ServiceSettings.EnableIncomingWebhooks = true })
+
+	hook, err := th.App.CreateIncomingWebhookForChannel(th.BasicUser.Id, th.BasicChannel, &model.IncomingWebhook{ChannelId: th.BasicChannel.Id})
+	require.Nil(t, err)
+	defer th.App.DeleteIncomingWebhook(hook.Id)
+
+	otherHook, err := th.App.CreateIncomingWebhookForChannel(th.BasicUser.Id, th.BasicChannel, &model.IncomingWebhook{ChannelId: th.BasicChannel.Id})
+	require.Nil(t, err)
+	defer th.App.DeleteIncomingWebhook(otherHook.Id)
+
+	post, err := th.App.HandleIncomingWebhook(hook.Id, &model.IncomingWebhookRequest{Text: "Build is running"})
+	require.Nil(t, err)
+	assert.Equal(t, hook.Id, post.Props[model.POST_PROPS_WEBHOOK_ID])
+
+	t.Run("update", func(t *testing.T) {
+		updated, err := th.App.HandleIncomingWebhook(hook.Id, &model.IncomingWebhookRequest{
+			Text:         "Build passed",
+			UpdatePostId: post.Id,
+			Attachments:  []*model.SlackAttachment{{Text: "All 42 tests passed"}},
+		})
+		require.Nil(t, err)
+		assert.Equal(t, post.Id, updated.Id)
+
+		saved, err := th.App.GetSinglePost(post.Id)
+		require.Nil(t, err)
+		assert.Equal(t, "Build passed", saved.Message)
+		assert.NotZero(t, saved.EditAt)
+		assert.Equal(t, hook.Id, saved.Props[model.POST_PROPS_WEBHOOK_ID])
+		assert.Equal(t, "true", saved.Props["from_webhook"])
+		assert.NotNil(t, saved.Props["attachments"])
+	})
+
+	t.Run("the webhook id can't be overridden", func(t *testing.T) {
+		created, err := th.App.HandleIncomingWebhook(otherHook.Id, &model.IncomingWebhookRequest{
+			Text:  "Pretending to be another webhook",
+			Props: model.StringInterface{model.POST_PROPS_WEBHOOK_ID: hook.Id},
+		})
+		require.Nil(t, err)
+		assert.Equal(t, otherHook.Id, created.Props[model.POST_PROPS_WEBHOOK_ID])
+	})
+
+	t.Run("another webhook can't update or delete the post", func(t *testing.T) {
+		_, err := th.App.HandleIncomingWebhook(otherHook.Id, &model.IncomingWebhookRequest{Text: "Build failed", UpdatePostId: post.Id})
+		require.NotNil(t, err)
+		assert.Equal(t, "web.incoming_webhook.post_not_owned.app_error", err.Id)
+
+		_, err = th.App.HandleIncomingWebhook(otherHook.Id, &model.IncomingWebhookRequest{DeletePostId: post.Id})
+		require.NotNil(t, err)
+		assert.Equal(t, "web.incoming_webhook.post_not_owned.app_error", err.Id)
+	})
+
+	t.Run("a post by a user can't be updated", func(t *testing.T) {
+		userPost := th.CreatePost(th.BasicChannel)
+
+		_, err := th.App.HandleIncomingWebhook(hook.Id, &model.IncomingWebhookRequest{Text: "Build failed", UpdatePostId: userPost.Id})
+		require.NotNil(t, err)
+		assert.Equal(t, "web.incoming_webhook.post_not_owned.app_error", err.Id)
+
+		// even if it claims to be from the webhook
+		forgedPost, err := th.App.CreatePost(&model.Post{
+			UserId:    th.BasicUser2.Id,
+			ChannelId: th.BasicChannel.Id,
+			Message:   "Build passed",
+			Props:     model.StringInterface{model.POST_PROPS_WEBHOOK_ID: hook.Id},
+		}, th.BasicChannel, false)
+		require.Nil(t, err)
+
+		_, err = th.App.HandleIncomingWebhook(hook.Id, &model.IncomingWebhookRequest{Text: "Build failed", UpdatePostId: forgedPost.Id})
+		require.NotNil(t, err)
+		assert.Equal(t, "web.incoming_webhook.post_not_owned.app_error", err.Id)
+	})
+
+	t.Run("too long to update", func(t *testing.T) {
+		_, err := th.App.HandleIncomingWebhook(hook.Id, &model.IncomingWebhookRequest{
+			Text:         strings.Repeat("a", th.App.MaxPostSize()+1),
+			UpdatePostId: post.Id,
+		})
+		require.NotNil(t, err)
+		assert.Equal(t, "web.incoming_webhook.update_post_length.app_error", err.Id)
+	})
+
+	t.Run("update and delete at once", func(t *testing.T) {
+		_, err := th.App.HandleIncomingWebhook(hook.Id, &model.IncomingWebhookRequest{Text: "Build failed", UpdatePostId: post.Id, DeletePostId: post.Id})
+		require.NotNil(t, err)
+		assert.Equal(t, "web.incoming_webhook.update_and_delete.app_error", err.Id)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		_, err := th.App.HandleIncomingWebhook(hook.Id, &model.IncomingWebhookRequest{DeletePostId: post.Id})
+		require.Nil(t, err)
+
+		_, err = th.App.GetSinglePost(post.Id)
+		assert.NotNil(t, err)
+
+		_, err = th.App.HandleIncomingWebhook(hook.Id, &model.IncomingWebhookRequest{Text: "Build passed", UpdatePostId: post.Id})
+		require.NotNil(t, err)
+		assert.Equal(t, "web.incoming_webhook.post.app_error", err.Id)
+	})
+}
+
 func TestSplitWebhookPost(t *testing.T) {
 	type TestCase struct {
 		Post     *model.Post
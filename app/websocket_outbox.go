@@ -0,0 +1,228 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const (
+	// WEBSOCKET_OUTBOX_REDELIVERY_WINDOW is how far back the events that weren't sent are sent again from when the
+	// outbox starts. Events older than this are deleted since clients will have reloaded the data by then.
+	WEBSOCKET_OUTBOX_REDELIVERY_WINDOW = 15 * time.Minute
+
+	WEBSOCKET_OUTBOX_QUEUE_SIZE        = 1000
+	WEBSOCKET_OUTBOX_BATCH_SIZE        = 1000
+	WEBSOCKET_OUTBOX_CLEANUP_TASK_NAME = "Clean Up WebSocket Outbox"
+)
+
+// webSocketOutbox sends the websocket events that are saved along with the changes that they're about, and marks them
+// as sent afterwards. When it starts, it first sends the recent events that were never marked as sent because the
+// server stopped after saving them, so clients that are connected to other servers in the cluster still hear about
+// them. Clients tell events apart by the ids of what they're about, so an event that's sent twice does no harm.
+type webSocketOutbox struct {
+	app   *App
+	queue chan *webSocketOutboxMessage
+
+	// publish sends an event to the connected clients
+	publish func(message *model.WebSocketEvent)
+
+	lock        sync.RWMutex
+	stopped     bool
+	done        sync.WaitGroup
+	cleanupTask *model.ScheduledTask
+}
+
+type webSocketOutboxMessage struct {
+	eventId string
+	message *model.WebSocketEvent
+}
+
+func newWebSocketOutbox(a *App, publish func(message *model.WebSocketEvent)) *webSocketOutbox {
+	return &webSocketOutbox{
+		app:     a,
+		queue:   make(chan *webSocketOutboxMessage, WEBSOCKET_OUTBOX_QUEUE_SIZE),
+		publish: publish,
+	}
+}
+
+func (o *webSocketOutbox) start() {
+	o.done.Add(1)
+	go o.run(model.GetMillis())
+
+	o.cleanupTask = model.CreateRecurringTask(WEBSOCKET_OUTBOX_CLEANUP_TASK_NAME, o.cleanUp, WEBSOCKET_OUTBOX_REDELIVERY_WINDOW)
+}
+
+func (o *webSocketOutbox) run(startedAt int64) {
+	defer o.done.Done()
+
+	o.redeliver(startedAt)
+
+	for message := range o.queue {
+		o.send(message)
+	}
+}
+
+// redeliver sends the unsent events from the redelivery window that were saved before the outbox started. Any saved
+// since then are already queued.
+func (o *webSocketOutbox) redeliver(startedAt int64) {
+	after := startedAt - int64(WEBSOCKET_OUTBOX_REDELIVERY_WINDOW/time.Millisecond)
+	count := 0
+
+	for {
+		result := <-o.app.Srv.Store.WebSocketOutbox().GetUnsent(after, startedAt, WEBSOCKET_OUTBOX_BATCH_SIZE)
+		if result.Err != nil {
+			mlog.Error(fmt.Sprintf("Failed to get the websocket events that weren't sent err=%v", result.Err.Error()))
+			break
+		}
+
+		events := result.Data.([]*model.WebSocketOutboxEvent)
+		for _, event := range events {
+			if message := o.app.webSocketEventFromOutbox(event); message != nil {
+				o.publish(message)
+				count++
+			}
+
+			if result := <-o.app.Srv.Store.WebSocketOutbox().MarkSent(event.Id, model.GetMillis()); result.Err != nil {
+				// Stop so that the same events aren't fetched again forever
+				mlog.Error(fmt.Sprintf("Failed to mark a websocket event as sent id=%v err=%v", event.Id, result.Err.Error()))
+				return
+			}
+		}
+
+		if len(events) < WEBSOCKET_OUTBOX_BATCH_SIZE {
+			break
+		}
+	}
+
+	if count > 0 {
+		mlog.Info(fmt.Sprintf("Sent %v websocket events that weren't sent before the server stopped", count))
+	}
+}
+
+func (o *webSocketOutbox) send(message *webSocketOutboxMessage) {
+	o.publish(message.message)
+	o.app.markWebSocketEventSent(message.eventId)
+}
+
+func (o *webSocketOutbox) cleanUp() {
+	endTime := model.GetMillis() - int64(WEBSOCKET_OUTBOX_REDELIVERY_WINDOW/time.Millisecond)
+
+	for {
+		result := <-o.app.Srv.Store.WebSocketOutbox().PermanentDeleteBatch(endTime, WEBSOCKET_OUTBOX_BATCH_SIZE)
+		if result.Err != nil {
+			mlog.Error(fmt.Sprintf("Failed to delete old websocket events err=%v", result.Err.Error()))
+			return
+		}
+
+		if result.Data.(int64) < WEBSOCKET_OUTBOX_BATCH_SIZE {
+			return
+		}
+	}
+}
+
+// add queues the message to be sent, waiting for space if the queue is full. The message is sent immediately if the
+// outbox has been stopped.
+func (o *webSocketOutbox) add(message *webSocketOutboxMessage) {
+	o.lock.RLock()
+
+	if o.stopped {
+		o.lock.RUnlock()
+		o.send(message)
+		return
+	}
+
+	o.queue <- message
+
+	o.lock.RUnlock()
+}
+
+// stop waits for every queued message to be sent. Any added afterwards are sent immediately.
+func (o *webSocketOutbox) stop() {
+	o.lock.Lock()
+	if o.stopped {
+		o.lock.Unlock()
+		return
+	}
+
+	o.stopped = true
+	close(o.queue)
+	o.lock.Unlock()
+
+	if o.cleanupTask != nil {
+		o.cleanupTask.Cancel()
+	}
+
+	o.done.Wait()
+}
+
+func (a *App) startWebSocketOutbox() {
+	if !*a.Config().ServiceSettings.ReliableWebsocketEvents {
+		return
+	}
+
+	a.webSocketOutbox = newWebSocketOutbox(a, a.Publish)
+	a.webSocketOutbox.start()
+}
+
+func (a *App) stopWebSocketOutbox() {
+	if a.webSocketOutbox != nil {
+		a.webSocketOutbox.stop()
+	}
+}
+
+// publishWebSocketOutboxEvent sends the message for the outbox event with the given id and marks the event as sent.
+// The message is sent straight away if the outbox isn't running.
+func (a *App) publishWebSocketOutboxEvent(eventId string, message *model.WebSocketEvent) {
+	if a.webSocketOutbox == nil {
+		a.Publish(message)
+		a.markWebSocketEventSent(eventId)
+		return
+	}
+
+	a.webSocketOutbox.add(&webSocketOutboxMessage{
+		eventId: eventId,
+		message: message,
+	})
+}
+
+func (a *App) markWebSocketEventSent(eventId string) {
+	if result := <-a.Srv.Store.WebSocketOutbox().MarkSent(eventId, model.GetMillis()); result.Err != nil {
+		mlog.Warn(fmt.Sprintf("Failed to mark a websocket event as sent, so it may be sent again id=%v err=%v", eventId, result.Err.Error()))
+	}
+}
+
+// webSocketEventFromOutbox returns the websocket event to send again for an outbox event, or nil if there's nothing
+// left to send. The posted event is made from the post as it is now, without the mentions that are only known when
+// the post is first sent, so clients show the post but don't notify anyone about it again.
+func (a *App) webSocketEventFromOutbox(event *model.WebSocketOutboxEvent) *model.WebSocketEvent {
+	if event.PostId == "" {
+		return event.WebSocketEvent()
+	}
+
+	post, err := a.GetSinglePost(event.PostId)
+	if err != nil {
+		// The post has been deleted since
+		return nil
+	}
+
+	channel, err := a.GetChannel(post.ChannelId)
+	if err != nil {
+		return nil
+	}
+
+	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_POSTED, "", post.ChannelId, "", nil)
+	message.Add("post", a.PostWithProxyAddedToImageURLs(post).ToJson())
+	message.Add("channel_type", channel.Type)
+	message.Add("channel_display_name", channel.DisplayName)
+	message.Add("channel_name", channel.Name)
+	message.Add("team_id", channel.TeamId)
+
+	return message
+}
@@ -0,0 +1,259 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+// webSocketOutboxTestStore keeps the outbox in memory.
+type webSocketOutboxTestStore struct {
+	store.Store
+
+	outbox *webSocketOutboxTestOutboxStore
+}
+
+func (s *webSocketOutboxTestStore) WebSocketOutbox() store.WebSocketOutboxStore { return s.outbox }
+
+type webSocketOutboxTestOutboxStore struct {
+	store.WebSocketOutboxStore
+
+	lock   sync.Mutex
+	events map[string]*model.WebSocketOutboxEvent
+}
+
+func (s *webSocketOutboxTestOutboxStore) save(event *model.WebSocketOutboxEvent) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	event.PreSave()
+	s.events[event.Id] = event
+}
+
+func (s *webSocketOutboxTestOutboxStore) sentAt(id string) int64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.events[id].SentAt
+}
+
+func (s *webSocketOutboxTestOutboxStore) GetUnsent(after int64, before int64, limit int) store.StoreChannel {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	events := []*model.WebSocketOutboxEvent{}
+	for _, event := range s.events {
+		if event.SentAt == 0 && event.CreateAt >= after && event.CreateAt < before {
+			copied := *event
+			events = append(events, &copied)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].CreateAt < events[j].CreateAt })
+	if len(events) > limit {
+		events = events[:limit]
+	}
+
+	return writeBehindTestResult(events)
+}
+
+func (s *webSocketOutboxTestOutboxStore) MarkSent(id string, sentAt int64) store.StoreChannel {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if event, ok := s.events[id]; ok && event.SentAt == 0 {
+		event.SentAt = sentAt
+	}
+
+	return writeBehindTestResult(nil)
+}
+
+func (s *webSocketOutboxTestOutboxStore) PermanentDeleteBatch(endTime int64, limit int64) store.StoreChannel {
+	return writeBehindTestResult(int64(0))
+}
+
+func newWebSocketOutboxTestApp() (*App, *webSocketOutboxTestOutboxStore) {
+	outbox := &webSocketOutboxTestOutboxStore{
+		events: map[string]*model.WebSocketOutboxEvent{},
+	}
+
+	cfg := &model.Config{}
+	cfg.SetDefaults()
+	*cfg.ServiceSettings.ReliableWebsocketEvents = true
+
+	a := &App{Srv: &Server{Store: &webSocketOutboxTestStore{outbox: outbox}}}
+	a.config.Store(cfg)
+
+	return a, outbox
+}
+
+// webSocketOutboxTestPublisher records the events that an outbox sends.
+type webSocketOutboxTestPublisher struct {
+	lock     sync.Mutex
+	messages []*model.WebSocketEvent
+}
+
+func (p *webSocketOutboxTestPublisher) publish(message *model.WebSocketEvent) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.messages = append(p.messages, message)
+}
+
+func (p *webSocketOutboxTestPublisher) reactions() []string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var reactions []string
+	for _, message := range p.messages {
+		reactions = append(reactions, message.Data["reaction"].(string))
+	}
+	return reactions
+}
+
+func newWebSocketOutboxTestEvent(reaction string, createAt int64) (*model.WebSocketEvent, *model.WebSocketOutboxEvent) {
+	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_REACTION_ADDED, "", model.NewId(), "", nil)
+	message.Add("reaction", reaction)
+
+	event := model.NewWebSocketOutboxEvent(message)
+	event.CreateAt = createAt
+
+	return message, event
+}
+
+func TestWebSocketOutboxSendsQueuedEvents(t *testing.T) {
+	a, outbox := newWebSocketOutboxTestApp()
+
+	publisher := &webSocketOutboxTestPublisher{}
+	a.webSocketOutbox = newWebSocketOutbox(a, publisher.publish)
+	a.webSocketOutbox.start()
+
+	message, event := newWebSocketOutboxTestEvent("queued", 0)
+	outbox.save(event)
+	a.publishWebSocketOutboxEvent(event.Id, message)
+
+	a.stopWebSocketOutbox()
+	assert.Equal(t, []string{"queued"}, publisher.reactions())
+	assert.NotZero(t, outbox.sentAt(event.Id))
+
+	// Events are sent straight away once the outbox has stopped
+	message, event = newWebSocketOutboxTestEvent("after stopping", 0)
+	outbox.save(event)
+	a.publishWebSocketOutboxEvent(event.Id, message)
+	assert.Equal(t, []string{"queued", "after stopping"}, publisher.reactions())
+	assert.NotZero(t, outbox.sentAt(event.Id))
+}
+
+func TestWebSocketOutboxRedeliversUnsentEvents(t *testing.T) {
+	a, outbox := newWebSocketOutboxTestApp()
+
+	now := model.GetMillis()
+
+	_, recent := newWebSocketOutboxTestEvent("recent", now-int64(time.Minute/time.Millisecond))
+	outbox.save(recent)
+
+	_, old := newWebSocketOutboxTestEvent("old", now-int64(2*WEBSOCKET_OUTBOX_REDELIVERY_WINDOW/time.Millisecond))
+	outbox.save(old)
+
+	_, sent := newWebSocketOutboxTestEvent("sent", now-1000)
+	outbox.save(sent)
+	outbox.MarkSent(sent.Id, now)
+
+	// The dispatcher dies after the change is saved but before the event is sent
+	dead := newWebSocketOutbox(a, func(message *model.WebSocketEvent) {
+		t.Fatal("the dead dispatcher shouldn't send anything")
+	})
+	a.webSocketOutbox = dead
+
+	message, crashed := newWebSocketOutboxTestEvent("crashed", now-1000)
+	outbox.save(crashed)
+	a.publishWebSocketOutboxEvent(crashed.Id, message)
+	assert.Zero(t, outbox.sentAt(crashed.Id))
+
+	// The server restarts with a new dispatcher
+	publisher := &webSocketOutboxTestPublisher{}
+	a.webSocketOutbox = newWebSocketOutbox(a, publisher.publish)
+	a.webSocketOutbox.start()
+	a.stopWebSocketOutbox()
+
+	assert.Equal(t, []string{"recent", "crashed"}, publisher.reactions())
+	assert.NotZero(t, outbox.sentAt(recent.Id))
+	assert.NotZero(t, outbox.sentAt(crashed.Id))
+	assert.Zero(t, outbox.sentAt(old.Id), "events from before the redelivery window shouldn't be sent again")
+}
+
+func TestWebSocketOutboxRedeliversAfterCrash(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) {
+		*cfg.ServiceSettings.ReliableWebsocketEvents = true
+	})
+
+	// The dispatcher dies after the changes are committed but before their events are sent
+	dead := newWebSocketOutbox(th.App, func(message *model.WebSocketEvent) {
+		t.Fatal("the dead dispatcher shouldn't send anything")
+	})
+	th.App.webSocketOutbox = dead
+
+	post := th.CreatePost(th.BasicChannel)
+
+	reaction, err := th.App.SaveReactionForPost(&model.Reaction{UserId: th.BasicUser.Id, PostId: post.Id, EmojiName: "smile"})
+	require.Nil(t, err)
+
+	channel := th.BasicChannel
+	channel.Header = "reliable header"
+	_, err = th.App.UpdateChannel(channel)
+	require.Nil(t, err)
+
+	// The reaction's event is queued by another goroutine
+	for start := time.Now(); len(dead.queue) < 3; time.Sleep(10 * time.Millisecond) {
+		require.True(t, time.Since(start) < 10*time.Second, "the events should be queued")
+	}
+
+	// The server restarts with a new dispatcher, after the events were created
+	time.Sleep(time.Millisecond)
+	publisher := &webSocketOutboxTestPublisher{}
+	th.App.webSocketOutbox = newWebSocketOutbox(th.App, publisher.publish)
+	th.App.webSocketOutbox.start()
+	th.App.stopWebSocketOutbox()
+
+	var posted, reactionAdded, channelUpdated bool
+	for _, message := range publisher.messages {
+		switch message.Event {
+		case model.WEBSOCKET_EVENT_POSTED:
+			if model.PostFromJson(strings.NewReader(message.Data["post"].(string))).Id == post.Id {
+				posted = true
+				assert.Equal(t, th.BasicChannel.Name, message.Data["channel_name"])
+			}
+		case model.WEBSOCKET_EVENT_REACTION_ADDED:
+			if model.ReactionFromJson(strings.NewReader(message.Data["reaction"].(string))).PostId == reaction.PostId {
+				reactionAdded = true
+			}
+		case model.WEBSOCKET_EVENT_CHANNEL_UPDATED:
+			if message.Broadcast.ChannelId == channel.Id {
+				channelUpdated = true
+			}
+		}
+	}
+	assert.True(t, posted, "the posted event should be sent again")
+	assert.True(t, reactionAdded, "the reaction added event should be sent again")
+	assert.True(t, channelUpdated, "the channel updated event should be sent again")
+
+	result := <-th.App.Srv.Store.WebSocketOutbox().GetUnsent(0, model.GetMillis()+1, 10000)
+	require.Nil(t, result.Err)
+	for _, event := range result.Data.([]*model.WebSocketOutboxEvent) {
+		assert.NotEqual(t, post.Id, event.Id, "the events should be marked as sent")
+	}
+}
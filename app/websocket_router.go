@@ -53,11 +53,18 @@ func (wr *WebSocketRouter) ServeWebSocket(conn *WebConn, r *model.WebSocketReque
 
 		if err != nil {
 			conn.WebSocket.Close()
+		} else if err := wr.app.CheckWebConnSessionFingerprint(session, conn); err != nil {
+			mlog.Info("Refused a websocket connection whose session doesn't match its fingerprint", mlog.String("user_id", session.UserId), mlog.Err(err))
+			conn.WebSocket.Close()
+		} else if wr.app.CheckMaintenanceMode(*session) != nil {
+			conn.closeForMaintenance()
 		} else {
-			wr.app.Go(func() {
-				wr.app.SetStatusOnline(session.UserId, session.Id, false)
-				wr.app.UpdateLastActivityAtIfNeeded(*session)
-			})
+			if !session.IsImpersonation {
+				wr.app.Go(func() {
+					wr.app.SetStatusOnline(session.UserId, session.Id, false)
+					wr.app.UpdateLastActivityAtIfNeeded(*session)
+				})
+			}
 
 			conn.SetSession(session)
 			conn.SetSessionToken(session.Token)
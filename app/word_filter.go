@@ -0,0 +1,190 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+const WORD_FILTER_ADMINS_PAGE_SIZE = 200
+
+func (a *App) GetTeamFilteredWords(teamId string) ([]string, *model.AppError) {
+	if result := <-a.Srv.Store.Team().GetFilteredWords(teamId); result.Err != nil {
+		return nil, result.Err
+	} else {
+		return result.Data.([]string), nil
+	}
+}
+
+// UpdateTeamFilteredWords replaces the words that are filtered from posts on a team, in addition to the ones
+// filtered everywhere by the config. Words are matched ignoring case, so they're saved in lowercase.
+func (a *App) UpdateTeamFilteredWords(teamId string, words []string) ([]string, *model.AppError) {
+	filteredWords := []string{}
+	for _, word := range words {
+		word = model.NormalizeFilteredWord(word)
+		if !model.IsValidFilteredWord(word) {
+			return nil, model.NewAppError("UpdateTeamFilteredWords", "app.team.update_filtered_words.word.app_error", map[string]interface{}{"Word": word}, "team_id="+teamId, http.StatusBadRequest)
+		}
+
+		if !utils.StringInSlice(word, filteredWords) {
+			filteredWords = append(filteredWords, word)
+		}
+	}
+
+	if len(filteredWords) > model.FILTERED_WORDS_MAX_COUNT {
+		return nil, model.NewAppError("UpdateTeamFilteredWords", "app.team.update_filtered_words.too_many.app_error", map[string]interface{}{"Max": model.FILTERED_WORDS_MAX_COUNT}, "team_id="+teamId, http.StatusBadRequest)
+	}
+
+	sort.Strings(filteredWords)
+
+	if result := <-a.Srv.Store.Team().UpdateFilteredWords(teamId, filteredWords); result.Err != nil {
+		return nil, result.Err
+	}
+
+	return filteredWords, nil
+}
+
+// filterPostWords checks the message of a post that's about to be saved for filtered words, masking them in the post
+// or returning an error if the post should be rejected because of them. When they should be flagged instead, the
+// words that were found are returned so that flagFilteredWords can be called once the post is saved.
+//
+// If channel is nil, filterPostWords will look up the channel corresponding to the post.
+func (a *App) filterPostWords(post *model.Post, channel *model.Channel) ([]string, *model.AppError) {
+	settings := a.Config().ServiceSettings
+	if *settings.WordFilterMode == model.WORD_FILTER_MODE_OFF || post.IsSystemMessage() || post.Message == "" {
+		return nil, nil
+	}
+
+	if *settings.WordFilterExemptSystemAdmins {
+		user, err := a.GetUser(post.UserId)
+		if err != nil {
+			return nil, err
+		}
+
+		if a.RolesGrantPermission(user.GetRoles(), model.PERMISSION_MANAGE_SYSTEM.Id) {
+			return nil, nil
+		}
+	}
+
+	if channel == nil {
+		var err *model.AppError
+		if channel, err = a.GetChannel(post.ChannelId); err != nil {
+			return nil, err
+		}
+	}
+
+	// Direct and group messages don't belong to a team, so only the words from the config apply to them
+	teamWords := []string{}
+	if channel.TeamId != "" {
+		var err *model.AppError
+		if teamWords, err = a.GetTeamFilteredWords(channel.TeamId); err != nil {
+			return nil, err
+		}
+	}
+
+	masked, found := model.NewWordFilter(settings.FilteredWords, teamWords).Filter(post.Message)
+	if len(found) == 0 {
+		return nil, nil
+	}
+
+	switch *settings.WordFilterMode {
+	case model.WORD_FILTER_MODE_MASK:
+		post.Message = masked
+		post.Hashtags, _ = model.ParseHashtags(post.Message)
+		return nil, nil
+	case model.WORD_FILTER_MODE_BLOCK:
+		return nil, model.NewAppError("filterPostWords", "app.post.word_filter.blocked.app_error", map[string]interface{}{"Words": strings.Join(found, ", ")}, "user_id="+post.UserId+", channel_id="+post.ChannelId, http.StatusBadRequest)
+	}
+
+	return found, nil
+}
+
+// flagFilteredWords lets the admins of the channel that a saved post is in know that it has filtered words in it
+// by sending each of them a direct message from the system bot.
+func (a *App) flagFilteredWords(post *model.Post, words []string) {
+	if len(words) == 0 {
+		return
+	}
+
+	a.Go(func() {
+		if err := a.sendFilteredWordsFlag(post, words); err != nil {
+			mlog.Error(fmt.Sprintf("Unable to flag filtered words, post_id=%v, err=%v", post.Id, err.Error()), mlog.String("post_id", post.Id))
+		}
+	})
+}
+
+func (a *App) sendFilteredWordsFlag(post *model.Post, words []string) *model.AppError {
+	channel, err := a.GetChannel(post.ChannelId)
+	if err != nil {
+		return err
+	}
+
+	user, err := a.GetUser(post.UserId)
+	if err != nil {
+		return err
+	}
+
+	adminIds := []string{}
+	for page := 0; ; page++ {
+		members, err := a.GetChannelMembersPage(channel.Id, page, WORD_FILTER_ADMINS_PAGE_SIZE)
+		if err != nil {
+			return err
+		}
+
+		for _, member := range *members {
+			if member.UserId != post.UserId && utils.StringInSlice(model.CHANNEL_ADMIN_ROLE_ID, strings.Fields(member.Roles)) {
+				adminIds = append(adminIds, member.UserId)
+			}
+		}
+
+		if len(*members) < WORD_FILTER_ADMINS_PAGE_SIZE {
+			break
+		}
+	}
+
+	if len(adminIds) == 0 {
+		return nil
+	}
+
+	bot, err := a.EnsureSystemBot()
+	if err != nil {
+		return err
+	}
+
+	for _, adminId := range adminIds {
+		admin, err := a.GetUser(adminId)
+		if err != nil {
+			return err
+		}
+
+		dm, err := a.GetDirectChannel(bot.Id, admin.Id)
+		if err != nil {
+			return err
+		}
+
+		flag := &model.Post{
+			ChannelId: dm.Id,
+			UserId:    bot.Id,
+			Message: utils.GetUserTranslations(admin.Locale)("app.post.word_filter.flagged", map[string]interface{}{
+				"Username":    user.Username,
+				"ChannelName": channel.Name,
+				"PostId":      post.Id,
+				"Words":       strings.Join(words, ", "),
+			}),
+		}
+
+		if _, err := a.CreatePost(flag, dm, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
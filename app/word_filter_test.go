@@ -0,0 +1,180 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestUpdateTeamFilteredWords(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	words, err := th.App.UpdateTeamFilteredWords(th.BasicTeam.Id, []string{"Zebra", " über", "zebra"})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"zebra", "über"}, words)
+
+	words, err = th.App.GetTeamFilteredWords(th.BasicTeam.Id)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"zebra", "über"}, words)
+
+	_, err = th.App.UpdateTeamFilteredWords(th.BasicTeam.Id, []string{"two words"})
+	require.NotNil(t, err)
+	assert.Equal(t, "app.team.update_filtered_words.word.app_error", err.Id)
+
+	words, err = th.App.UpdateTeamFilteredWords(th.BasicTeam.Id, []string{})
+	require.Nil(t, err)
+	assert.Empty(t, words)
+}
+
+func TestCreatePostWordFilter(t *testing.T) {
+	th := Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+
+	settings := th.App.Config().ServiceSettings
+	defer th.App.UpdateConfig(func(cfg *model.Config) {
+		cfg.ServiceSettings.WordFilterMode = settings.WordFilterMode
+		cfg.ServiceSettings.FilteredWords = settings.FilteredWords
+		cfg.ServiceSettings.WordFilterExemptSystemAdmins = settings.WordFilterExemptSystemAdmins
+	})
+
+	_, err := th.App.UpdateTeamFilteredWords(th.BasicTeam.Id, []string{"darn"})
+	require.Nil(t, err)
+
+	setMode := func(mode string) {
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.WordFilterMode = mode })
+	}
+
+	createPost := func(userId, channelId, message string) (*model.Post, *model.AppError) {
+		return th.App.CreatePostAsUser(&model.Post{
+			ChannelId: channelId,
+			UserId:    userId,
+			Message:   message,
+		})
+	}
+
+	t.Run("off", func(t *testing.T) {
+		setMode(model.WORD_FILTER_MODE_OFF)
+
+		post, err := createPost(th.BasicUser.Id, th.BasicChannel.Id, "darn it")
+		require.Nil(t, err)
+		assert.Equal(t, "darn it", post.Message)
+	})
+
+	t.Run("mask", func(t *testing.T) {
+		setMode(model.WORD_FILTER_MODE_MASK)
+
+		post, err := createPost(th.BasicUser.Id, th.BasicChannel.Id, "Darn it, #darn")
+		require.Nil(t, err)
+		assert.Equal(t, "**** it, #****", post.Message)
+		assert.Equal(t, "", post.Hashtags)
+
+		post, err = createPost(th.BasicUser.Id, th.BasicChannel.Id, "fine")
+		require.Nil(t, err)
+
+		post.Message = "darn, edited"
+		post, err = th.App.UpdatePost(post, false)
+		require.Nil(t, err)
+		assert.Equal(t, "****, edited", post.Message, "should filter edits")
+	})
+
+	t.Run("block", func(t *testing.T) {
+		setMode(model.WORD_FILTER_MODE_BLOCK)
+
+		_, err := createPost(th.BasicUser.Id, th.BasicChannel.Id, "darn it")
+		require.NotNil(t, err)
+		assert.Equal(t, "app.post.word_filter.blocked.app_error", err.Id)
+
+		_, err = createPost(th.BasicUser.Id, th.BasicChannel.Id, "darnation")
+		require.Nil(t, err, "should only block whole words")
+
+		// Direct messages aren't on a team, so only the words from the config are filtered in them
+		dm := th.CreateDmChannel(th.BasicUser2)
+		_, err = createPost(th.BasicUser.Id, dm.Id, "darn it")
+		require.Nil(t, err)
+
+		th.App.UpdateConfig(func(cfg *model.Config) { cfg.ServiceSettings.FilteredWords = []string{"heck"} })
+
+		_, err = createPost(th.BasicUser.Id, dm.Id, "heck")
+		require.NotNil(t, err)
+		_, err = createPost(th.BasicUser.Id, th.BasicChannel.Id, "heck")
+		require.NotNil(t, err, "should filter the words from the config on teams too")
+	})
+
+	t.Run("flag", func(t *testing.T) {
+		setMode(model.WORD_FILTER_MODE_FLAG)
+
+		channel := th.CreateChannel(th.BasicTeam)
+		th.AddUserToChannel(th.BasicUser2, channel)
+		_, err := th.App.UpdateChannelMemberRoles(channel.Id, th.BasicUser2.Id, model.CHANNEL_USER_ROLE_ID+" "+model.CHANNEL_ADMIN_ROLE_ID)
+		require.Nil(t, err)
+
+		post, err := createPost(th.BasicUser.Id, channel.Id, "darn it")
+		require.Nil(t, err)
+		assert.Equal(t, "darn it", post.Message)
+
+		bot, err := th.App.EnsureSystemBot()
+		require.Nil(t, err)
+
+		dm, err := th.App.GetDirectChannel(bot.Id, th.BasicUser2.Id)
+		require.Nil(t, err)
+
+		// The admins are sent their messages in the background
+		var flag *model.Post
+		for i := 0; i < 50 && flag == nil; i++ {
+			time.Sleep(100 * time.Millisecond)
+
+			posts, err := th.App.GetPosts(dm.Id, 0, 10)
+			require.Nil(t, err)
+			for _, p := range posts.Posts {
+				if strings.Contains(p.Message, post.Id) {
+					flag = p
+				}
+			}
+		}
+
+		require.NotNil(t, flag, "should have notified the channel admin")
+		assert.Equal(t, bot.Id, flag.UserId)
+		assert.Contains(t, flag.Message, "darn")
+	})
+
+	t.Run("list updates take effect", func(t *testing.T) {
+		setMode(model.WORD_FILTER_MODE_BLOCK)
+
+		_, err := createPost(th.BasicUser.Id, th.BasicChannel.Id, "gosh")
+		require.Nil(t, err)
+
+		_, err = th.App.UpdateTeamFilteredWords(th.BasicTeam.Id, []string{"gosh"})
+		require.Nil(t, err)
+
+		_, err = createPost(th.BasicUser.Id, th.BasicChannel.Id, "gosh")
+		require.NotNil(t, err)
+
+		_, err = createPost(th.BasicUser.Id, th.BasicChannel.Id, "darn")
+		require.Nil(t, err, "should no longer filter the old words")
+	})
+
+	t.Run("system admins", func(t *testing.T) {
+		setMode(model.WORD_FILTER_MODE_BLOCK)
+		th.LinkUserToTeam(th.SystemAdminUser, th.BasicTeam)
+
+		_, err := createPost(th.SystemAdminUser.Id, th.BasicChannel.Id, "gosh")
+		require.NotNil(t, err)
+
+		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.WordFilterExemptSystemAdmins = true })
+
+		_, err = createPost(th.SystemAdminUser.Id, th.BasicChannel.Id, "gosh")
+		require.Nil(t, err)
+
+		_, err = createPost(th.BasicUser.Id, th.BasicChannel.Id, "gosh")
+		require.NotNil(t, err)
+	})
+}
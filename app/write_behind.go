@@ -0,0 +1,218 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const WRITE_BEHIND_FLUSH_TASK_NAME = "Flush Write-Behind Updates"
+
+// pendingStatus is a user's latest status that hasn't been written to the database yet.
+type pendingStatus struct {
+	status *model.Status
+
+	// save is set if more than the status's LastActivityAt has changed since it was last written
+	save bool
+}
+
+// startWriteBehindFlush periodically writes the channel views and statuses that have been queued since the last
+// flush, so that a user viewing channels or using the API repeatedly within the interval only writes to the database
+// once. The websocket events for those changes are still sent straight away.
+func (a *App) startWriteBehindFlush() {
+	interval := *a.Config().ServiceSettings.WriteBehindIntervalMilliseconds
+	if interval <= 0 {
+		return
+	}
+
+	a.writeBehindLock.Lock()
+	defer a.writeBehindLock.Unlock()
+
+	a.pendingChannelViews = make(map[string]map[string]*model.ChannelViewedAt)
+	a.pendingStatuses = make(map[string]*pendingStatus)
+	a.writeBehindTask = model.CreateRecurringTask(WRITE_BEHIND_FLUSH_TASK_NAME, a.FlushWriteBehind, time.Duration(interval)*time.Millisecond)
+}
+
+func (a *App) stopWriteBehindFlush() {
+	a.writeBehindLock.Lock()
+	task := a.writeBehindTask
+	a.writeBehindTask = nil
+	a.writeBehindLock.Unlock()
+
+	if task != nil {
+		task.Cancel()
+	}
+
+	a.FlushWriteBehind()
+}
+
+// queueChannelViews records that the user has viewed the channels so that their read positions are moved forward on
+// the next flush. It returns false if write-behind isn't running, in which case nothing is queued.
+func (a *App) queueChannelViews(userId string, views []*model.ChannelViewedAt) bool {
+	a.writeBehindLock.Lock()
+	defer a.writeBehindLock.Unlock()
+
+	if a.writeBehindTask == nil {
+		return false
+	}
+
+	pending := a.pendingChannelViews[userId]
+	if pending == nil {
+		pending = make(map[string]*model.ChannelViewedAt)
+		a.pendingChannelViews[userId] = pending
+	}
+
+	for _, view := range views {
+		if previous, ok := pending[view.ChannelId]; ok && previous.LastPostAt > view.LastPostAt {
+			continue
+		}
+
+		pending[view.ChannelId] = view
+	}
+
+	return true
+}
+
+// getPendingChannelView returns the user's view of the channel that hasn't been written to the database yet, or nil
+// if there isn't one.
+func (a *App) getPendingChannelView(userId string, channelId string) *model.ChannelViewedAt {
+	a.writeBehindLock.Lock()
+	defer a.writeBehindLock.Unlock()
+
+	return a.pendingChannelViews[userId][channelId]
+}
+
+// queueStatus records the user's status to be written on the next flush. It returns false if write-behind isn't
+// running, in which case nothing is queued.
+func (a *App) queueStatus(status *model.Status, save bool) bool {
+	a.writeBehindLock.Lock()
+	defer a.writeBehindLock.Unlock()
+
+	if a.writeBehindTask == nil {
+		return false
+	}
+
+	if previous, ok := a.pendingStatuses[status.UserId]; ok && previous.save {
+		save = true
+	}
+
+	statusCopy := *status
+	a.pendingStatuses[status.UserId] = &pendingStatus{
+		status: &statusCopy,
+		save:   save,
+	}
+
+	return true
+}
+
+// getPendingStatus returns the user's status that hasn't been written to the database yet, or nil if there isn't
+// one.
+func (a *App) getPendingStatus(userId string) *model.Status {
+	a.writeBehindLock.Lock()
+	defer a.writeBehindLock.Unlock()
+
+	if pending, ok := a.pendingStatuses[userId]; ok {
+		statusCopy := *pending.status
+		return &statusCopy
+	}
+
+	return nil
+}
+
+// FlushWriteBehind writes every queued channel view and status to the database.
+func (a *App) FlushWriteBehind() {
+	a.writeBehindFlushLock.Lock()
+	defer a.writeBehindFlushLock.Unlock()
+
+	a.writeBehindLock.Lock()
+	channelViews := a.pendingChannelViews
+	statuses := a.pendingStatuses
+	a.pendingChannelViews = make(map[string]map[string]*model.ChannelViewedAt)
+	a.pendingStatuses = make(map[string]*pendingStatus)
+	a.writeBehindLock.Unlock()
+
+	for userId, views := range channelViews {
+		a.flushChannelViews(userId, views)
+	}
+
+	for _, pending := range statuses {
+		a.flushStatus(pending)
+	}
+}
+
+// flushChannelViewsForUser writes the user's queued channel views to the database. This must be called before
+// reading or changing anything that depends on the user's read positions, such as their unread counts.
+func (a *App) flushChannelViewsForUser(userId string) {
+	a.writeBehindFlushLock.Lock()
+	defer a.writeBehindFlushLock.Unlock()
+
+	a.writeBehindLock.Lock()
+	views, ok := a.pendingChannelViews[userId]
+	delete(a.pendingChannelViews, userId)
+	a.writeBehindLock.Unlock()
+
+	if ok {
+		a.flushChannelViews(userId, views)
+	}
+}
+
+func (a *App) flushChannelViews(userId string, pending map[string]*model.ChannelViewedAt) {
+	views := make([]*model.ChannelViewedAt, 0, len(pending))
+	for _, view := range pending {
+		views = append(views, view)
+	}
+
+	if result := <-a.Srv.Store.Channel().UpdateViewedAt(userId, views); result.Err != nil {
+		mlog.Error(fmt.Sprintf("Failed to update last viewed at for user_id=%v, err=%v", userId, result.Err), mlog.String("user_id", userId))
+	}
+}
+
+func (a *App) flushStatus(pending *pendingStatus) {
+	status := pending.status
+
+	if pending.save {
+		if result := <-a.Srv.Store.Status().SaveOrUpdate(status); result.Err != nil {
+			mlog.Error(fmt.Sprintf("Failed to save status for user_id=%v, err=%v", status.UserId, result.Err), mlog.String("user_id", status.UserId))
+		}
+	} else {
+		if result := <-a.Srv.Store.Status().UpdateLastActivityAt(status.UserId, status.LastActivityAt); result.Err != nil {
+			mlog.Error(fmt.Sprintf("Failed to save status for user_id=%v, err=%v", status.UserId, result.Err), mlog.String("user_id", status.UserId))
+		}
+	}
+}
+
+// updateLastViewedAt moves the user's read positions in the channels up to their latest posts, returning the time of
+// each channel's latest post. The database is updated on the next flush if write-behind is running.
+func (a *App) updateLastViewedAt(channelIds []string, userId string) (map[string]int64, *model.AppError) {
+	result := <-a.Srv.Store.Channel().GetViewedAt(channelIds)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	views := result.Data.([]*model.ChannelViewedAt)
+
+	if !a.queueChannelViews(userId, views) {
+		if result := <-a.Srv.Store.Channel().UpdateViewedAt(userId, views); result.Err != nil {
+			return nil, result.Err
+		}
+	}
+
+	times := make(map[string]int64, len(views))
+	for _, view := range views {
+		times[view.ChannelId] = view.LastPostAt
+	}
+
+	return times, nil
+}
+
+// saveStatus writes the user's status to the database, or queues it for the next flush if write-behind is running.
+// save should be set if anything other than the status's LastActivityAt has changed.
+func (a *App) saveStatus(status *model.Status, save bool) {
+	if !a.queueStatus(status, save) {
+		a.flushStatus(&pendingStatus{status: status, save: save})
+	}
+}
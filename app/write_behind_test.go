@@ -0,0 +1,257 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func writeBehindTestResult(data interface{}) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		result.Data = data
+	})
+}
+
+// writeBehindTestStore keeps just enough channel and status state in memory to count how often they're written.
+type writeBehindTestStore struct {
+	store.Store
+
+	channels *writeBehindTestChannelStore
+	statuses *writeBehindTestStatusStore
+}
+
+func (s *writeBehindTestStore) Channel() store.ChannelStore { return s.channels }
+func (s *writeBehindTestStore) Status() store.StatusStore   { return s.statuses }
+
+type writeBehindTestChannelStore struct {
+	store.ChannelStore
+
+	lock     sync.Mutex
+	channels map[string]model.ChannelViewedAt
+	members  map[string]model.ChannelViewedAt
+	updates  int
+}
+
+func (s *writeBehindTestChannelStore) setChannel(channelId string, lastPostAt int64, totalMsgCount int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.channels[channelId] = model.ChannelViewedAt{ChannelId: channelId, LastPostAt: lastPostAt, TotalMsgCount: totalMsgCount}
+}
+
+func (s *writeBehindTestChannelStore) getMember(channelId string, userId string) model.ChannelViewedAt {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.members[userId+channelId]
+}
+
+func (s *writeBehindTestChannelStore) getUpdates() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.updates
+}
+
+func (s *writeBehindTestChannelStore) GetViewedAt(channelIds []string) store.StoreChannel {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	views := []*model.ChannelViewedAt{}
+	for _, channelId := range channelIds {
+		if channel, ok := s.channels[channelId]; ok {
+			views = append(views, &channel)
+		}
+	}
+
+	return writeBehindTestResult(views)
+}
+
+func (s *writeBehindTestChannelStore) UpdateViewedAt(userId string, views []*model.ChannelViewedAt) store.StoreChannel {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.updates++
+	for _, view := range views {
+		s.members[userId+view.ChannelId] = *view
+	}
+
+	return writeBehindTestResult(nil)
+}
+
+func (s *writeBehindTestChannelStore) GetChannelUnread(channelId, userId string) store.StoreChannel {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	member := s.members[userId+channelId]
+	return writeBehindTestResult(&model.ChannelUnread{
+		ChannelId:    channelId,
+		MsgCount:     s.channels[channelId].TotalMsgCount - member.TotalMsgCount,
+		LastViewedAt: member.LastPostAt,
+	})
+}
+
+type writeBehindTestStatusStore struct {
+	store.StatusStore
+
+	lock  sync.Mutex
+	saved []model.Status
+}
+
+func (s *writeBehindTestStatusStore) getSaved() []model.Status {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([]model.Status{}, s.saved...)
+}
+
+func (s *writeBehindTestStatusStore) Get(userId string) store.StoreChannel {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for i := len(s.saved) - 1; i >= 0; i-- {
+		if s.saved[i].UserId == userId {
+			status := s.saved[i]
+			return writeBehindTestResult(&status)
+		}
+	}
+
+	return store.Do(func(result *store.StoreResult) {
+		result.Err = model.NewAppError("writeBehindTestStatusStore.Get", "store.sql_status.get.missing.app_error", nil, "", http.StatusNotFound)
+	})
+}
+
+func (s *writeBehindTestStatusStore) SaveOrUpdate(status *model.Status) store.StoreChannel {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.saved = append(s.saved, *status)
+	return writeBehindTestResult(status)
+}
+
+func newWriteBehindTestApp(intervalMilliseconds int) (*App, *writeBehindTestStore) {
+	testStore := &writeBehindTestStore{
+		channels: &writeBehindTestChannelStore{
+			channels: map[string]model.ChannelViewedAt{},
+			members:  map[string]model.ChannelViewedAt{},
+		},
+		statuses: &writeBehindTestStatusStore{},
+	}
+
+	cfg := &model.Config{}
+	cfg.SetDefaults()
+	*cfg.ServiceSettings.WriteBehindIntervalMilliseconds = intervalMilliseconds
+
+	a := &App{Srv: &Server{Store: testStore}}
+	a.config.Store(cfg)
+	a.startWriteBehindFlush()
+
+	return a, testStore
+}
+
+func TestWriteBehindCoalescesChannelViews(t *testing.T) {
+	// The interval is long enough that only the test flushes
+	a, testStore := newWriteBehindTestApp(60 * 60 * 1000)
+	defer a.stopWriteBehindFlush()
+
+	channelId1 := model.NewId()
+	channelId2 := model.NewId()
+	testStore.channels.setChannel(channelId1, 1000, 10)
+	testStore.channels.setChannel(channelId2, 2000, 20)
+
+	userId1 := model.NewId()
+	userId2 := model.NewId()
+
+	for i := 0; i < 10; i++ {
+		require.Nil(t, a.UpdateChannelLastViewedAt([]string{channelId1}, userId1))
+		require.Nil(t, a.UpdateChannelLastViewedAt([]string{channelId2}, userId1))
+		require.Nil(t, a.UpdateChannelLastViewedAt([]string{channelId1, channelId2}, userId2))
+	}
+	assert.Equal(t, 0, testStore.channels.getUpdates(), "shouldn't have written anything yet")
+
+	a.FlushWriteBehind()
+	assert.Equal(t, 2, testStore.channels.getUpdates(), "should've written once for each user")
+
+	assert.Equal(t, int64(1000), testStore.channels.getMember(channelId1, userId1).LastPostAt)
+	assert.Equal(t, int64(20), testStore.channels.getMember(channelId2, userId1).TotalMsgCount)
+	assert.Equal(t, int64(2000), testStore.channels.getMember(channelId2, userId2).LastPostAt)
+
+	a.FlushWriteBehind()
+	assert.Equal(t, 2, testStore.channels.getUpdates(), "nothing should've been left to write")
+}
+
+func TestWriteBehindFlushesBeforeReadingUnreads(t *testing.T) {
+	a, testStore := newWriteBehindTestApp(60 * 60 * 1000)
+	defer a.stopWriteBehindFlush()
+
+	channelId := model.NewId()
+	userId := model.NewId()
+	testStore.channels.setChannel(channelId, 1000, 10)
+
+	times, err := a.updateLastViewedAt([]string{channelId}, userId)
+	require.Nil(t, err)
+	assert.Equal(t, map[string]int64{channelId: 1000}, times)
+
+	// Posts made after the channel was viewed are still unread
+	testStore.channels.setChannel(channelId, 3000, 12)
+
+	unread, err := a.GetChannelUnread(channelId, userId)
+	require.Nil(t, err)
+	assert.Equal(t, int64(2), unread.MsgCount)
+	assert.Equal(t, int64(1000), unread.LastViewedAt)
+	assert.Equal(t, 1, testStore.channels.getUpdates())
+	assert.Nil(t, a.getPendingChannelView(userId, channelId))
+}
+
+func TestWriteBehindCoalescesStatuses(t *testing.T) {
+	a, testStore := newWriteBehindTestApp(60 * 60 * 1000)
+	defer a.stopWriteBehindFlush()
+
+	ClearStatusCache()
+	defer ClearStatusCache()
+
+	userId := model.NewId()
+
+	a.SetStatusDoNotDisturb(userId)
+	a.SetStatusOutOfOffice(userId)
+	a.SetStatusOffline(userId, true)
+	assert.Empty(t, testStore.statuses.getSaved(), "shouldn't have written anything yet")
+
+	ClearStatusCache()
+	status, err := a.GetStatus(userId)
+	require.Nil(t, err)
+	assert.Equal(t, model.STATUS_OFFLINE, status.Status, "should've used the status that hasn't been written yet")
+
+	a.stopWriteBehindFlush()
+
+	saved := testStore.statuses.getSaved()
+	require.Len(t, saved, 1, "should've only written the latest status")
+	assert.Equal(t, model.STATUS_OFFLINE, saved[0].Status)
+	assert.True(t, saved[0].Manual)
+}
+
+func TestWriteBehindDisabled(t *testing.T) {
+	a, testStore := newWriteBehindTestApp(0)
+	defer a.stopWriteBehindFlush()
+
+	channelId := model.NewId()
+	testStore.channels.setChannel(channelId, 1000, 10)
+
+	require.Nil(t, a.UpdateChannelLastViewedAt([]string{channelId}, model.NewId()))
+	assert.Equal(t, 1, testStore.channels.getUpdates(), "should've written straight away")
+
+	ClearStatusCache()
+	defer ClearStatusCache()
+
+	a.SetStatusDoNotDisturb(model.NewId())
+	assert.Len(t, testStore.statuses.getSaved(), 1, "should've written straight away")
+}
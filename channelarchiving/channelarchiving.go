@@ -0,0 +1,19 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package channelarchiving
+
+import (
+	"github.com/mattermost/mattermost-server/app"
+	tjobs "github.com/mattermost/mattermost-server/einterfaces/jobs"
+)
+
+type ChannelArchivingJobInterfaceImpl struct {
+	App *app.App
+}
+
+func init() {
+	app.RegisterJobsChannelArchivingJobInterface(func(a *app.App) tjobs.ChannelArchivingJobInterface {
+		return &ChannelArchivingJobInterfaceImpl{a}
+	})
+}
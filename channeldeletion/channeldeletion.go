@@ -0,0 +1,19 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package channeldeletion
+
+import (
+	"github.com/mattermost/mattermost-server/app"
+	tjobs "github.com/mattermost/mattermost-server/einterfaces/jobs"
+)
+
+type ChannelDeletionJobInterfaceImpl struct {
+	App *app.App
+}
+
+func init() {
+	app.RegisterJobsChannelDeletionJobInterface(func(a *app.App) tjobs.ChannelDeletionJobInterface {
+		return &ChannelDeletionJobInterfaceImpl{a}
+	})
+}
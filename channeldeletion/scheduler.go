@@ -0,0 +1,63 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package channeldeletion
+
+import (
+	"time"
+
+	"github.com/mattermost/mattermost-server/app"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// SCHEDULE_INTERVAL is how often channels whose grace period has passed are looked for, so they're deleted within
+// about an hour of being due.
+const SCHEDULE_INTERVAL = time.Hour
+
+type Scheduler struct {
+	App *app.App
+}
+
+func (m *ChannelDeletionJobInterfaceImpl) MakeScheduler() model.Scheduler {
+	return &Scheduler{m.App}
+}
+
+func (scheduler *Scheduler) Name() string {
+	return "ChannelDeletionScheduler"
+}
+
+func (scheduler *Scheduler) JobType() string {
+	return model.JOB_TYPE_CHANNEL_DELETION
+}
+
+func (scheduler *Scheduler) Enabled(cfg *model.Config) bool {
+	return true
+}
+
+// NextScheduleTime is an hour after the last successful run, or now if there hasn't been one.
+func (scheduler *Scheduler) NextScheduleTime(cfg *model.Config, now time.Time, pendingJobs bool, lastSuccessfulJob *model.Job) *time.Time {
+	if lastSuccessfulJob == nil {
+		return &now
+	}
+
+	nextTime := time.Unix(0, lastSuccessfulJob.LastActivityAt*int64(time.Millisecond)).Add(SCHEDULE_INTERVAL)
+	if nextTime.Before(now) {
+		return &now
+	}
+
+	return &nextTime
+}
+
+func (scheduler *Scheduler) ScheduleJob(cfg *model.Config, pendingJobs bool, lastSuccessfulJob *model.Job) (*model.Job, *model.AppError) {
+	if pendingJobs {
+		return nil, nil
+	}
+
+	data := map[string]string{}
+
+	if job, err := scheduler.App.Jobs.CreateJob(model.JOB_TYPE_CHANNEL_DELETION, data); err != nil {
+		return nil, err
+	} else {
+		return job, nil
+	}
+}
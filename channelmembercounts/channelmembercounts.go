@@ -0,0 +1,19 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package channelmembercounts
+
+import (
+	"github.com/mattermost/mattermost-server/app"
+	tjobs "github.com/mattermost/mattermost-server/einterfaces/jobs"
+)
+
+type ChannelMemberCountsJobInterfaceImpl struct {
+	App *app.App
+}
+
+func init() {
+	app.RegisterJobsChannelMemberCountsJobInterface(func(a *app.App) tjobs.ChannelMemberCountsJobInterface {
+		return &ChannelMemberCountsJobInterfaceImpl{a}
+	})
+}
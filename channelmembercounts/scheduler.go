@@ -0,0 +1,53 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package channelmembercounts
+
+import (
+	"time"
+
+	"github.com/mattermost/mattermost-server/app"
+	"github.com/mattermost/mattermost-server/jobs"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// JOB_START_HOUR is the hour of the night, in the server's time zone, at which the counts are reconciled.
+const JOB_START_HOUR = 3
+
+type Scheduler struct {
+	App *app.App
+}
+
+func (m *ChannelMemberCountsJobInterfaceImpl) MakeScheduler() model.Scheduler {
+	return &Scheduler{m.App}
+}
+
+func (scheduler *Scheduler) Name() string {
+	return "ChannelMemberCountsScheduler"
+}
+
+func (scheduler *Scheduler) JobType() string {
+	return model.JOB_TYPE_CHANNEL_MEMBER_COUNTS
+}
+
+func (scheduler *Scheduler) Enabled(cfg *model.Config) bool {
+	return true
+}
+
+func (scheduler *Scheduler) NextScheduleTime(cfg *model.Config, now time.Time, pendingJobs bool, lastSuccessfulJob *model.Job) *time.Time {
+	return jobs.GenerateNextStartDateTime(now, time.Date(0, 1, 1, JOB_START_HOUR, 0, 0, 0, time.Local))
+}
+
+func (scheduler *Scheduler) ScheduleJob(cfg *model.Config, pendingJobs bool, lastSuccessfulJob *model.Job) (*model.Job, *model.AppError) {
+	if pendingJobs {
+		return nil, nil
+	}
+
+	data := map[string]string{}
+
+	if job, err := scheduler.App.Jobs.CreateJob(model.JOB_TYPE_CHANNEL_MEMBER_COUNTS, data); err != nil {
+		return nil, err
+	} else {
+		return job, nil
+	}
+}
@@ -6,6 +6,7 @@ package commands
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/mattermost/mattermost-server/app"
 	"github.com/mattermost/mattermost-server/model"
@@ -56,7 +57,7 @@ var DeleteChannelsCmd = &cobra.Command{
 	Use:   "delete [channels]",
 	Short: "Delete channels",
 	Long: `Permanently delete some channels.
-Permanently deletes a channel along with all related information including posts from the database.
+Archives the channels and marks them to be permanently deleted along with all related information, including posts and files, once the ServiceSettings.ChannelDeletionGracePeriodDays grace period has passed. They can be restored with "channel restore" until then.
 Channels can be specified by [team]:[channel]. ie. myteam:mychannel or by channel ID.`,
 	Example: "  channel delete myteam:mychannel",
 	RunE:    deleteChannelsCmdF,
@@ -66,7 +67,7 @@ var ListChannelsCmd = &cobra.Command{
 	Use:   "list [teams]",
 	Short: "List all channels on specified teams.",
 	Long: `List all channels on specified teams.
-Archived channels are appended with ' (archived)'.`,
+Archived channels are appended with ' (archived)', or have archived set to true in table and JSON output.`,
 	Example: "  channel list myteam",
 	RunE:    listChannelsCmdF,
 }
@@ -85,6 +86,7 @@ var RestoreChannelsCmd = &cobra.Command{
 	Use:   "restore [channels]",
 	Short: "Restore some channels",
 	Long: `Restore a previously deleted channel
+Channels that are marked to be permanently deleted can be restored until their grace period has passed.
 Channels can be specified by [team]:[channel]. ie. myteam:mychannel or by channel ID.`,
 	Example: "  channel restore myteam:mychannel",
 	RunE:    restoreChannelsCmdF,
@@ -111,10 +113,17 @@ func init() {
 
 	DeleteChannelsCmd.Flags().Bool("confirm", false, "Confirm you really want to delete the channels.")
 
+	AddOutputFlag(ListChannelsCmd)
+
 	ModifyChannelCmd.Flags().Bool("private", false, "Convert the channel to a private channel")
 	ModifyChannelCmd.Flags().Bool("public", false, "Convert the channel to a public channel")
 	ModifyChannelCmd.Flags().String("username", "", "Required. Username who changes the channel privacy.")
 
+	withLocalMode(ChannelCreateCmd, createChannelLocalCmdF)
+	withLocalMode(RemoveChannelUsersCmd, removeChannelUsersLocalCmdF)
+	withLocalMode(AddChannelUsersCmd, addChannelUsersLocalCmdF)
+	withLocalMode(ArchiveChannelsCmd, archiveChannelsLocalCmdF)
+
 	ChannelCmd.AddCommand(
 		ChannelCreateCmd,
 		RemoveChannelUsersCmd,
@@ -137,17 +146,56 @@ func createChannelCmdF(command *cobra.Command, args []string) error {
 	}
 	defer a.Shutdown()
 
+	channel, teamArg, err := getChannelFromCreateFlags(command)
+	if err != nil {
+		return err
+	}
+
+	team := getTeamFromTeamArg(a, teamArg)
+	if team == nil {
+		return errors.New("Unable to find team: " + teamArg)
+	}
+	channel.TeamId = team.Id
+
+	if _, err := a.CreateChannel(channel, false); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func createChannelLocalCmdF(client *model.Client4, command *cobra.Command, args []string) error {
+	channel, teamArg, err := getChannelFromCreateFlags(command)
+	if err != nil {
+		return err
+	}
+
+	team := getTeamFromTeamArgLocal(client, teamArg)
+	if team == nil {
+		return errors.New("Unable to find team: " + teamArg)
+	}
+	channel.TeamId = team.Id
+
+	if _, resp := client.CreateChannel(channel); resp.Error != nil {
+		return resp.Error
+	}
+
+	return nil
+}
+
+// getChannelFromCreateFlags returns the channel to create, without its team, along with the team it's to be created in.
+func getChannelFromCreateFlags(command *cobra.Command) (*model.Channel, string, error) {
 	name, errn := command.Flags().GetString("name")
 	if errn != nil || name == "" {
-		return errors.New("Name is required")
+		return nil, "", errors.New("Name is required")
 	}
 	displayname, errdn := command.Flags().GetString("display_name")
 	if errdn != nil || displayname == "" {
-		return errors.New("Display Name is required")
+		return nil, "", errors.New("Display Name is required")
 	}
 	teamArg, errteam := command.Flags().GetString("team")
 	if errteam != nil || teamArg == "" {
-		return errors.New("Team is required")
+		return nil, "", errors.New("Team is required")
 	}
 	header, _ := command.Flags().GetString("header")
 	purpose, _ := command.Flags().GetString("purpose")
@@ -158,13 +206,7 @@ func createChannelCmdF(command *cobra.Command, args []string) error {
 		channelType = model.CHANNEL_PRIVATE
 	}
 
-	team := getTeamFromTeamArg(a, teamArg)
-	if team == nil {
-		return errors.New("Unable to find team: " + teamArg)
-	}
-
 	channel := &model.Channel{
-		TeamId:      team.Id,
 		Name:        name,
 		DisplayName: displayname,
 		Header:      header,
@@ -173,11 +215,7 @@ func createChannelCmdF(command *cobra.Command, args []string) error {
 		CreatorId:   "",
 	}
 
-	if _, err := a.CreateChannel(channel, false); err != nil {
-		return err
-	}
-
-	return nil
+	return channel, teamArg, nil
 }
 
 func removeChannelUsersCmdF(command *cobra.Command, args []string) error {
@@ -214,6 +252,30 @@ func removeUserFromChannel(a *app.App, channel *model.Channel, user *model.User,
 	}
 }
 
+func removeChannelUsersLocalCmdF(client *model.Client4, command *cobra.Command, args []string) error {
+	if len(args) < 2 {
+		return errors.New("Not enough arguments.")
+	}
+
+	channel := getChannelFromChannelArgLocal(client, args[0])
+	if channel == nil {
+		return errors.New("Unable to find channel '" + args[0] + "'")
+	}
+
+	users := getUsersFromUserArgsLocal(client, args[1:])
+	for i, user := range users {
+		if user == nil {
+			CommandPrintErrorln("Can't find user '" + args[i+1] + "'")
+			continue
+		}
+		if _, resp := client.RemoveUserFromChannel(channel.Id, user.Id); resp.Error != nil {
+			CommandPrintErrorln("Unable to remove '" + args[i+1] + "' from " + channel.Name + ". Error: " + resp.Error.Error())
+		}
+	}
+
+	return nil
+}
+
 func addChannelUsersCmdF(command *cobra.Command, args []string) error {
 	a, err := InitDBCommandContextCobra(command)
 	if err != nil {
@@ -248,6 +310,30 @@ func addUserToChannel(a *app.App, channel *model.Channel, user *model.User, user
 	}
 }
 
+func addChannelUsersLocalCmdF(client *model.Client4, command *cobra.Command, args []string) error {
+	if len(args) < 2 {
+		return errors.New("Not enough arguments.")
+	}
+
+	channel := getChannelFromChannelArgLocal(client, args[0])
+	if channel == nil {
+		return errors.New("Unable to find channel '" + args[0] + "'")
+	}
+
+	users := getUsersFromUserArgsLocal(client, args[1:])
+	for i, user := range users {
+		if user == nil {
+			CommandPrintErrorln("Can't find user '" + args[i+1] + "'")
+			continue
+		}
+		if _, resp := client.AddChannelMember(channel.Id, user.Id); resp.Error != nil {
+			CommandPrintErrorln("Unable to add '" + args[i+1] + "' from " + channel.Name + ". Error: " + resp.Error.Error())
+		}
+	}
+
+	return nil
+}
+
 func archiveChannelsCmdF(command *cobra.Command, args []string) error {
 	a, err := InitDBCommandContextCobra(command)
 	if err != nil {
@@ -273,6 +359,25 @@ func archiveChannelsCmdF(command *cobra.Command, args []string) error {
 	return nil
 }
 
+func archiveChannelsLocalCmdF(client *model.Client4, command *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return errors.New("Enter at least one channel to archive.")
+	}
+
+	channels := getChannelsFromChannelArgsLocal(client, args)
+	for i, channel := range channels {
+		if channel == nil {
+			CommandPrintErrorln("Unable to find channel '" + args[i] + "'")
+			continue
+		}
+		if _, resp := client.DeleteChannel(channel.Id); resp.Error != nil {
+			CommandPrintErrorln("Unable to archive channel '" + channel.Name + "' error: " + resp.Error.Error())
+		}
+	}
+
+	return nil
+}
+
 func deleteChannelsCmdF(command *cobra.Command, args []string) error {
 	a, err := InitDBCommandContextCobra(command)
 	if err != nil {
@@ -287,7 +392,7 @@ func deleteChannelsCmdF(command *cobra.Command, args []string) error {
 	confirmFlag, _ := command.Flags().GetBool("confirm")
 	if !confirmFlag {
 		var confirm string
-		CommandPrettyPrintln("Are you sure you want to delete the channels specified?  All data will be permanently deleted? (YES/NO): ")
+		CommandPrettyPrintln("Are you sure you want to delete the channels specified?  All data will be permanently deleted once the grace period has passed. (YES/NO): ")
 		fmt.Scanln(&confirm)
 		if confirm != "YES" {
 			return errors.New("ABORTED: You did not answer YES exactly, in all capitals.")
@@ -300,20 +405,17 @@ func deleteChannelsCmdF(command *cobra.Command, args []string) error {
 			CommandPrintErrorln("Unable to find channel '" + args[i] + "'")
 			continue
 		}
-		if err := deleteChannel(a, channel); err != nil {
+		if marked, err := a.MarkChannelForPermanentDeletion(channel, ""); err != nil {
 			CommandPrintErrorln("Unable to delete channel '" + channel.Name + "' error: " + err.Error())
 		} else {
-			CommandPrettyPrintln("Deleted channel '" + channel.Name + "'")
+			permanentDeleteAt := time.Unix(0, marked.PermanentDeleteAt*int64(time.Millisecond)).UTC().Format(time.RFC3339)
+			CommandPrettyPrintln("Channel '" + channel.Name + "' will be permanently deleted after " + permanentDeleteAt)
 		}
 	}
 
 	return nil
 }
 
-func deleteChannel(a *app.App, channel *model.Channel) *model.AppError {
-	return a.PermanentDeleteChannel(channel)
-}
-
 func moveChannelsCmdF(command *cobra.Command, args []string) error {
 	a, err := InitDBCommandContextCobra(command)
 	if err != nil {
@@ -400,6 +502,12 @@ func listChannelsCmdF(command *cobra.Command, args []string) error {
 		return errors.New("Enter at least one team.")
 	}
 
+	printer, err := NewPrinter(command)
+	if err != nil {
+		return err
+	}
+
+	records := []*channelRecord{}
 	teams := getTeamsFromTeamArgs(a, args)
 	for i, team := range teams {
 		if team == nil {
@@ -412,16 +520,40 @@ func listChannelsCmdF(command *cobra.Command, args []string) error {
 			channels := result.Data.([]*model.Channel)
 
 			for _, channel := range channels {
-				if channel.DeleteAt > 0 {
-					CommandPrettyPrintln(channel.Name + " (archived)")
-				} else {
-					CommandPrettyPrintln(channel.Name)
-				}
+				records = append(records, newChannelRecord(team, channel))
 			}
 		}
 	}
 
-	return nil
+	return printer.PrintRecords(records)
+}
+
+// channelRecord is how a channel is printed by channel list.
+type channelRecord struct {
+	Id          string `json:"id"`
+	Team        string `json:"team"`
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Type        string `json:"type"`
+	Archived    bool   `json:"archived"`
+}
+
+func newChannelRecord(team *model.Team, channel *model.Channel) *channelRecord {
+	return &channelRecord{
+		Id:          channel.Id,
+		Team:        team.Name,
+		Name:        channel.Name,
+		DisplayName: channel.DisplayName,
+		Type:        channel.Type,
+		Archived:    channel.DeleteAt > 0,
+	}
+}
+
+func (r *channelRecord) String() string {
+	if r.Archived {
+		return r.Name + " (archived)"
+	}
+	return r.Name
 }
 
 func restoreChannelsCmdF(command *cobra.Command, args []string) error {
@@ -441,8 +573,8 @@ func restoreChannelsCmdF(command *cobra.Command, args []string) error {
 			CommandPrintErrorln("Unable to find channel '" + args[i] + "'")
 			continue
 		}
-		if result := <-a.Srv.Store.Channel().SetDeleteAt(channel.Id, 0, model.GetMillis()); result.Err != nil {
-			CommandPrintErrorln("Unable to restore channel '" + args[i] + "'")
+		if _, err := a.RestoreChannel(channel); err != nil {
+			CommandPrintErrorln("Unable to restore channel '" + args[i] + "' error: " + err.Error())
 		}
 	}
 
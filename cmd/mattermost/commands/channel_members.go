@@ -0,0 +1,145 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mattermost/mattermost-server/app"
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/spf13/cobra"
+)
+
+var ChannelMembersCmd = &cobra.Command{
+	Use:   "members",
+	Short: "Management of channel members",
+}
+
+var ChannelMembersHistoryCmd = &cobra.Command{
+	Use:   "history [channel]",
+	Short: "Show who joined and left a channel",
+	Long: `Show who joined and left a channel, and who added or removed them, most recent first.
+Channel can be specified by [team]:[channel]. ie. myteam:mychannel or by channel ID.
+Times can be given as a date (2006-01-02) or in RFC 3339 format (2006-01-02T15:04:05Z).`,
+	Example: "  channel members history myteam:finance --since 2018-01-01",
+	RunE:    channelMembersHistoryCmdF,
+}
+
+func init() {
+	ChannelMembersHistoryCmd.Flags().String("since", "", "Only show memberships started or ended at or after this time.")
+	ChannelMembersHistoryCmd.Flags().String("until", "", "Only show memberships started or ended at or before this time. Defaults to now.")
+	ChannelMembersHistoryCmd.Flags().Int("page", 0, "Page number to fetch.")
+	ChannelMembersHistoryCmd.Flags().Int("per-page", 200, "Number of memberships to fetch per page.")
+
+	ChannelMembersCmd.AddCommand(
+		ChannelMembersHistoryCmd,
+	)
+
+	ChannelCmd.AddCommand(ChannelMembersCmd)
+}
+
+func parseTimeFlag(command *cobra.Command, name string) (int64, error) {
+	value, _ := command.Flags().GetString(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UnixNano() / int64(time.Millisecond), nil
+		}
+	}
+
+	return 0, errors.New("Invalid time for --" + name + ": " + value)
+}
+
+func channelMembersHistoryCmdF(command *cobra.Command, args []string) error {
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	if len(args) != 1 {
+		return errors.New("Enter one channel to show the history of.")
+	}
+
+	channel := getChannelFromChannelArg(a, args[0])
+	if channel == nil {
+		return errors.New("Unable to find channel '" + args[0] + "'")
+	}
+
+	since, err := parseTimeFlag(command, "since")
+	if err != nil {
+		return err
+	}
+	until, err := parseTimeFlag(command, "until")
+	if err != nil {
+		return err
+	}
+
+	page, _ := command.Flags().GetInt("page")
+	perPage, _ := command.Flags().GetInt("per-page")
+	if page < 0 || perPage <= 0 {
+		return errors.New("Invalid page or per-page")
+	}
+
+	history, appErr := a.GetChannelMemberHistory(channel.Id, since, until, page, perPage)
+	if appErr != nil {
+		return appErr
+	}
+
+	actors := map[string]string{}
+	for _, entry := range history {
+		if entry.JoinActorId == entry.UserId || entry.JoinActorId == "" {
+			CommandPrettyPrintln(fmt.Sprintf("%s  %s joined", formatHistoryTime(entry.JoinTime), entry.Username))
+		} else {
+			CommandPrettyPrintln(fmt.Sprintf("%s  %s was added by %s", formatHistoryTime(entry.JoinTime), entry.Username, historyActorName(a, actors, entry.JoinActorId)))
+		}
+
+		if entry.LeaveTime == nil {
+			continue
+		}
+
+		leaveTime := formatHistoryTime(*entry.LeaveTime)
+		if entry.LeaveActorId == entry.UserId || entry.LeaveActorId == "" {
+			if entry.LeaveReason == model.CHANNEL_MEMBER_LEAVE_REASON_LEFT_TEAM {
+				CommandPrettyPrintln(fmt.Sprintf("%s  %s left the team", leaveTime, entry.Username))
+			} else {
+				CommandPrettyPrintln(fmt.Sprintf("%s  %s left", leaveTime, entry.Username))
+			}
+		} else if entry.LeaveReason == model.CHANNEL_MEMBER_LEAVE_REASON_LEFT_TEAM {
+			CommandPrettyPrintln(fmt.Sprintf("%s  %s was removed from the team by %s", leaveTime, entry.Username, historyActorName(a, actors, entry.LeaveActorId)))
+		} else {
+			CommandPrettyPrintln(fmt.Sprintf("%s  %s was removed by %s", leaveTime, entry.Username, historyActorName(a, actors, entry.LeaveActorId)))
+		}
+	}
+
+	return nil
+}
+
+func formatHistoryTime(millis int64) string {
+	return time.Unix(0, millis*int64(time.Millisecond)).UTC().Format(time.RFC3339)
+}
+
+// historyActorName returns the username of the actor of a membership change, caching it in actors.
+func historyActorName(a *app.App, actors map[string]string, actorId string) string {
+	if actorId == model.CHANNEL_MEMBER_HISTORY_ACTOR_SYSTEM {
+		return "the system"
+	}
+
+	if name, ok := actors[actorId]; ok {
+		return name
+	}
+
+	name := actorId
+	if result := <-a.Srv.Store.User().Get(actorId); result.Err == nil {
+		name = result.Data.(*model.User).Username
+	}
+	actors[actorId] = name
+
+	return name
+}
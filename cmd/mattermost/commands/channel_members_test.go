@@ -0,0 +1,37 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/api4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelMembersHistory(t *testing.T) {
+	th := api4.Setup().InitBasic()
+	defer th.TearDown()
+
+	channel := th.CreatePublicChannel()
+	channelArg := th.BasicTeam.Name + ":" + channel.Name
+
+	CheckCommand(t, "channel", "add", channelArg, th.BasicUser2.Email)
+	CheckCommand(t, "channel", "remove", channelArg, th.BasicUser2.Email)
+
+	output := CheckCommand(t, "channel", "members", "history", channelArg)
+	assert.Contains(t, output, th.BasicUser.Username+" joined")
+	assert.Contains(t, output, th.BasicUser2.Username+" was added by the system")
+	assert.Contains(t, output, th.BasicUser2.Username+" was removed by the system")
+
+	output = CheckCommand(t, "channel", "members", "history", channelArg, "--until", "2000-01-01")
+	assert.NotContains(t, output, th.BasicUser2.Username)
+
+	// should fail because the date is invalid
+	require.Error(t, RunCommand(t, "channel", "members", "history", channelArg, "--since", "yesterday"))
+
+	// should fail because the channel does not exist
+	require.Error(t, RunCommand(t, "channel", "members", "history", th.BasicTeam.Name+":doesnotexist"))
+}
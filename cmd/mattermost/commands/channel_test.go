@@ -86,6 +86,11 @@ func TestListChannels(t *testing.T) {
 	if !strings.Contains(string(output), channel.Name+" (archived)") {
 		t.Fatal("should have archived channel")
 	}
+
+	output = CheckCommand(t, "channel", "list", th.BasicTeam.Name, "--output", "json")
+	if !strings.Contains(output, `"name": "town-square"`) || !strings.Contains(output, `"archived": true`) {
+		t.Fatal("should list the channels as JSON")
+	}
 }
 
 func TestRestoreChannel(t *testing.T) {
@@ -58,3 +58,36 @@ func getChannelFromChannelArg(a *app.App, channelArg string) *model.Channel {
 
 	return channel
 }
+
+func getChannelsFromChannelArgsLocal(client *model.Client4, channelArgs []string) []*model.Channel {
+	channels := make([]*model.Channel, 0, len(channelArgs))
+	for _, channelArg := range channelArgs {
+		channel := getChannelFromChannelArgLocal(client, channelArg)
+		channels = append(channels, channel)
+	}
+	return channels
+}
+
+func getChannelFromChannelArgLocal(client *model.Client4, channelArg string) *model.Channel {
+	teamArg, channelPart := parseChannelArg(channelArg)
+	if teamArg == "" && channelPart == "" {
+		return nil
+	}
+
+	if teamArg != "" {
+		team := getTeamFromTeamArgLocal(client, teamArg)
+		if team == nil {
+			return nil
+		}
+
+		if channel, resp := client.GetChannelByName(channelPart, team.Id, ""); resp.Error == nil {
+			return channel
+		}
+	}
+
+	if channel, resp := client.GetChannel(channelPart, ""); resp.Error == nil {
+		return channel
+	}
+
+	return nil
+}
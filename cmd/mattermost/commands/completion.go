@@ -0,0 +1,40 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"errors"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var CompletionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh]",
+	Short: "Generate a shell completion script",
+	Long: `Write a completion script for the mattermost command to standard output.
+To load completion in bash, add "source <(mattermost completion bash)" to ~/.bashrc. For zsh, write the script to a file named _mattermost in a directory on $fpath.`,
+	Example:   "  completion bash > /etc/bash_completion.d/mattermost",
+	ValidArgs: []string{"bash", "zsh"},
+	RunE:      completionCmdF,
+}
+
+func init() {
+	RootCmd.AddCommand(CompletionCmd)
+}
+
+func completionCmdF(command *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return errors.New("Enter a shell: bash or zsh.")
+	}
+
+	switch args[0] {
+	case "bash":
+		return RootCmd.GenBashCompletion(os.Stdout)
+	case "zsh":
+		return RootCmd.GenZshCompletion(os.Stdout)
+	}
+
+	return errors.New("Unsupported shell '" + args[0] + "'. Enter bash or zsh.")
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/spf13/cobra"
+)
+
+var ComplianceExportCmd = &cobra.Command{
+	Use:   "compliance",
+	Short: "Export posts for compliance",
+	Long: `Export the posts made during a period of time, including deleted posts and the versions of posts from before they were edited, along with the members of their channels.
+Dates can be given as YYYY-MM-DD, in which case --to includes the whole day, or as RFC3339 timestamps.
+Dates and the times in CSV exports are in the timezone given by --timezone, while Actiance exports always use UTC.`,
+	Example: "export compliance --from 2018-01-01 --to 2018-01-31 --format csv --output exports/",
+	RunE:    complianceExportCmdF,
+}
+
+func init() {
+	ComplianceExportCmd.Flags().String("from", "", "The date or time of the earliest post to export.")
+	ComplianceExportCmd.Flags().String("to", "", "The date or time of the latest post to export. Defaults to now.")
+	ComplianceExportCmd.Flags().String("format", model.COMPLIANCE_EXPORT_FORMAT_CSV, "The format to export data in, either csv or actiance.")
+	ComplianceExportCmd.Flags().StringP("output", "o", "", "The directory to write the export to.")
+	ComplianceExportCmd.Flags().String("timezone", "UTC", "The timezone to use for dates and for times in CSV exports, such as America/New_York.")
+	MessageExportCmd.AddCommand(ComplianceExportCmd)
+}
+
+func complianceExportCmdF(command *cobra.Command, args []string) error {
+	timezone, err := command.Flags().GetString("timezone")
+	if err != nil {
+		return errors.New("timezone flag error")
+	}
+
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return errors.New("timezone must be a timezone name such as UTC or America/New_York")
+	}
+
+	from, err := command.Flags().GetString("from")
+	if err != nil || from == "" {
+		return errors.New("from flag error")
+	}
+
+	startTime, err := parseComplianceExportTime(from, false, location)
+	if err != nil {
+		return errors.New("from must be a date (YYYY-MM-DD) or an RFC3339 time")
+	}
+
+	endTime := model.GetMillis()
+	if to, err := command.Flags().GetString("to"); err != nil {
+		return errors.New("to flag error")
+	} else if to != "" {
+		if endTime, err = parseComplianceExportTime(to, true, location); err != nil {
+			return errors.New("to must be a date (YYYY-MM-DD) or an RFC3339 time")
+		}
+	}
+
+	if endTime < startTime {
+		return errors.New("to must not be before from")
+	}
+
+	format, err := command.Flags().GetString("format")
+	if err != nil {
+		return errors.New("format flag error")
+	} else if format != model.COMPLIANCE_EXPORT_FORMAT_CSV && format != model.COMPLIANCE_EXPORT_FORMAT_ACTIANCE {
+		return errors.New("unsupported export format")
+	}
+
+	output, err := command.Flags().GetString("output")
+	if err != nil || output == "" {
+		return errors.New("output flag error")
+	}
+
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	job, appErr := a.ExportCompliance(startTime, endTime, format, output, location)
+	if appErr != nil {
+		return appErr
+	}
+
+	CommandPrettyPrintln(fmt.Sprintf("Exported %v posts to %v as %v", job.Count, output, job.JobName()))
+
+	return nil
+}
+
+// parseComplianceExportTime parses either a date or an RFC3339 time into milliseconds since the epoch. Dates
+// are taken to be in location, and endOfDay picks the last millisecond of the date instead of the first.
+func parseComplianceExportTime(value string, endOfDay bool, location *time.Location) (int64, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, location); err == nil {
+		if endOfDay {
+			t = t.Add(24*time.Hour - time.Millisecond)
+		}
+		return t.UnixNano() / int64(time.Millisecond), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, err
+	}
+
+	return t.UnixNano() / int64(time.Millisecond), nil
+}
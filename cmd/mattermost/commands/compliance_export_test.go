@@ -0,0 +1,50 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComplianceExportInvalidFlags(t *testing.T) {
+	// these should all fail fast without needing the database
+	require.Error(t, RunCommand(t, "export", "compliance", "--output", "out"))
+	require.Error(t, RunCommand(t, "export", "compliance", "--from", "yesterday", "--output", "out"))
+	require.Error(t, RunCommand(t, "export", "compliance", "--from", "2018-02-01", "--to", "2018-01-01", "--output", "out"))
+	require.Error(t, RunCommand(t, "export", "compliance", "--from", "2018-01-01", "--format", "pdf", "--output", "out"))
+	require.Error(t, RunCommand(t, "export", "compliance", "--from", "2018-01-01"))
+	require.Error(t, RunCommand(t, "export", "compliance", "--from", "2018-01-01", "--timezone", "Mars/Olympus_Mons", "--output", "out"))
+}
+
+func TestParseComplianceExportTime(t *testing.T) {
+	start, err := parseComplianceExportTime("2018-01-01", false, time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1514764800000), start)
+
+	end, err := parseComplianceExportTime("2018-01-01", true, time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1514851199999), end)
+
+	exact, err := parseComplianceExportTime("2018-01-01T12:00:00+02:00", true, time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1514800800000), exact)
+
+	_, err = parseComplianceExportTime("01/01/2018", false, time.UTC)
+	assert.Error(t, err)
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+
+	start, err = parseComplianceExportTime("2018-01-01", false, tokyo)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1514732400000), start)
+
+	exact, err = parseComplianceExportTime("2018-01-01T12:00:00+02:00", false, tokyo)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1514800800000), exact, "explicit offsets aren't affected by the timezone")
+}
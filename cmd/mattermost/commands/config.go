@@ -4,9 +4,15 @@
 package commands
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"reflect"
+	"strings"
 
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/utils"
@@ -25,9 +31,56 @@ var ValidateConfigCmd = &cobra.Command{
 	RunE:  configValidateCmdF,
 }
 
+var ExportConfigCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the config",
+	Long: `Write the config to a file, or print it if --output isn't given. With --exclude-secrets, passwords, salts, keys and database connection strings are replaced with placeholders, so that the file can be shared or kept in version control and imported into another server with "config import".
+
+With --local, the config is the running server's. The server never sends its secrets, so they're always replaced with placeholders.`,
+	Example: "  config export --exclude-secrets -o settings.json",
+	RunE:    configExportCmdF,
+}
+
+var ImportConfigCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Import settings into the config",
+	Long: `Apply the settings in a file onto the config. Only the settings in the file are changed, and secrets that the file has as placeholders are left as they are. Settings that the server doesn't know about fail the import, and so does a config that isn't valid once they're applied.
+
+The changes are shown before they're written, and need to be confirmed unless --yes is given. A running server picks them up from the config file, or with --local, they're saved by the running server itself.`,
+	Example: "  config import settings.json --merge",
+	Args:    cobra.ExactArgs(1),
+	RunE:    configImportCmdF,
+}
+
+var SetConfigCmd = &cobra.Command{
+	Use:   "set [setting] [values...]",
+	Short: "Change a setting in the config",
+	Long: `Change a setting, named by its path like "TeamSettings.SiteName", and leave every other setting as it is. Settings that are lists are set to all of the values, and settings that aren't text or lists of text are given as JSON, like "true" or "50".
+
+With --local, only the setting is sent to the running server, so it doesn't overwrite any other setting that's changed at the same time, such as in the System Console.`,
+	Example: `  config set TeamSettings.SiteName Mattermost
+  config set TeamSettings.MaxUsersPerTeam 100
+  config set ServiceSettings.DisabledAPIEndpoints "POST /api/v4/users" "POST /api/v4/teams"`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: configSetCmdF,
+}
+
 func init() {
+	ExportConfigCmd.Flags().Bool("exclude-secrets", false, "Replace secrets with placeholders.")
+	ExportConfigCmd.Flags().StringP("output", "o", "", "The file to write the config to.")
+
+	ImportConfigCmd.Flags().Bool("merge", false, "Apply the settings in the file onto the current config. This is the only kind of import supported.")
+	ImportConfigCmd.Flags().Bool("yes", false, "Write the changes without asking for confirmation.")
+
+	withLocalMode(ExportConfigCmd, configExportLocalCmdF)
+	withLocalMode(ImportConfigCmd, configImportLocalCmdF)
+	withLocalMode(SetConfigCmd, configSetLocalCmdF)
+
 	ConfigCmd.AddCommand(
 		ValidateConfigCmd,
+		ExportConfigCmd,
+		ImportConfigCmd,
+		SetConfigCmd,
 	)
 	RootCmd.AddCommand(ConfigCmd)
 }
@@ -65,3 +118,289 @@ func configValidateCmdF(command *cobra.Command, args []string) error {
 	CommandPrettyPrintln("The document is valid")
 	return nil
 }
+
+// loadConfigFile reads the config file without applying the environment overrides, since those shouldn't be exported
+// or written back to the file.
+func loadConfigFile(command *cobra.Command) (*model.Config, string, error) {
+	if err := utils.TranslationsPreInit(); err != nil {
+		return nil, "", err
+	}
+	model.AppErrorInit(utils.T)
+
+	filePath, err := command.Flags().GetString("config")
+	if err != nil {
+		return nil, "", err
+	}
+
+	filePath = utils.FindConfigFile(filePath)
+	if filePath == "" {
+		return nil, "", errors.New("Unable to find the config file")
+	}
+
+	config, _, err := utils.ReadConfigFile(filePath, false)
+	if err != nil {
+		return nil, "", err
+	}
+	config.SetDefaults()
+
+	return config, filePath, nil
+}
+
+func configExportCmdF(command *cobra.Command, args []string) error {
+	config, _, err := loadConfigFile(command)
+	if err != nil {
+		return err
+	}
+
+	if excludeSecrets, _ := command.Flags().GetBool("exclude-secrets"); excludeSecrets {
+		config.SanitizeForExport()
+	}
+
+	return writeExportedConfig(command, config)
+}
+
+func configExportLocalCmdF(client *model.Client4, command *cobra.Command, args []string) error {
+	config, resp := client.GetConfig()
+	if resp.Error != nil {
+		return errors.New("Unable to get the config. Error: " + resp.Error.Error())
+	}
+
+	// Some secrets are already sanitized by the server, so sanitize the rest too rather than export a mix
+	config.SanitizeForExport()
+
+	return writeExportedConfig(command, config)
+}
+
+func writeExportedConfig(command *cobra.Command, config *model.Config) error {
+	b, err := json.MarshalIndent(config, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	output, _ := command.Flags().GetString("output")
+	if output == "" {
+		CommandPrettyPrintln(string(b))
+		return nil
+	}
+
+	if err := ioutil.WriteFile(output, b, 0600); err != nil {
+		return err
+	}
+
+	CommandPrettyPrintln("Exported the config to " + output)
+	return nil
+}
+
+func configImportCmdF(command *cobra.Command, args []string) error {
+	if merge, _ := command.Flags().GetBool("merge"); !merge {
+		return errors.New("Only --merge imports are supported")
+	}
+
+	config, filePath, err := loadConfigFile(command)
+	if err != nil {
+		return err
+	}
+
+	merged, changes, err := mergeConfigFile(command, config, args[0], filePath)
+	if err != nil || len(changes) == 0 {
+		return err
+	}
+
+	if err := saveConfigFile(command, config, merged, filePath); err != nil {
+		return err
+	}
+
+	CommandPrettyPrintln(fmt.Sprintf("Changed %v settings", len(changes)))
+	return nil
+}
+
+func configImportLocalCmdF(client *model.Client4, command *cobra.Command, args []string) error {
+	if merge, _ := command.Flags().GetBool("merge"); !merge {
+		return errors.New("Only --merge imports are supported")
+	}
+
+	// The secrets in the config are placeholders, which the server leaves as they are when it's saved
+	config, resp := client.GetConfig()
+	if resp.Error != nil {
+		return errors.New("Unable to get the config. Error: " + resp.Error.Error())
+	}
+
+	_, changes, err := mergeConfigFile(command, config, args[0], "the running server")
+	if err != nil || len(changes) == 0 {
+		return err
+	}
+
+	// Only the settings in the file are sent, so that settings changed since the config was read are left alone
+	data, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	var settings map[string]interface{}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return err
+	}
+
+	if _, resp := client.PatchConfig(settings); resp.Error != nil {
+		return errors.New("Unable to save the config. Error: " + resp.Error.Error())
+	}
+
+	CommandPrettyPrintln(fmt.Sprintf("Changed %v settings", len(changes)))
+	return nil
+}
+
+func configSetCmdF(command *cobra.Command, args []string) error {
+	settings, err := configSettingPatch(args[0], args[1:])
+	if err != nil {
+		return err
+	}
+
+	config, filePath, err := loadConfigFile(command)
+	if err != nil {
+		return err
+	}
+
+	b, _ := json.Marshal(settings)
+	merged, changes, err := mergeConfigSettings(command, config, bytes.NewReader(b), filePath, false)
+	if err != nil || len(changes) == 0 {
+		return err
+	}
+
+	return saveConfigFile(command, config, merged, filePath)
+}
+
+func configSetLocalCmdF(client *model.Client4, command *cobra.Command, args []string) error {
+	settings, err := configSettingPatch(args[0], args[1:])
+	if err != nil {
+		return err
+	}
+
+	if _, resp := client.PatchConfig(settings); resp.Error != nil {
+		return errors.New("Unable to save the config. Error: " + resp.Error.Error())
+	}
+
+	CommandPrettyPrintln("Changed " + args[0])
+	return nil
+}
+
+// configSettingPatch returns the settings that change the one at the given path, like "TeamSettings.SiteName", to the
+// values. Text settings take their value as it is, lists of text take all of the values, and any other setting takes
+// its value as JSON.
+func configSettingPatch(setting string, values []string) (map[string]interface{}, error) {
+	names := strings.Split(setting, ".")
+
+	t := reflect.TypeOf(model.Config{})
+	for i, name := range names {
+		if t.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("%v isn't a setting", setting)
+		}
+
+		field, ok := t.FieldByNameFunc(func(fieldName string) bool { return strings.EqualFold(fieldName, name) })
+		if !ok {
+			return nil, fmt.Errorf("%v isn't a setting", setting)
+		}
+		names[i] = field.Name
+
+		t = field.Type
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+	}
+
+	var value interface{}
+	switch {
+	case t.Kind() == reflect.Struct:
+		return nil, fmt.Errorf("%v is a section of the config rather than a setting", setting)
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String:
+		list := make([]interface{}, len(values))
+		for i, v := range values {
+			list[i] = v
+		}
+		value = list
+	case len(values) != 1:
+		return nil, fmt.Errorf("%v takes a single value", setting)
+	case t.Kind() == reflect.String:
+		value = values[0]
+	default:
+		if err := json.Unmarshal([]byte(values[0]), &value); err != nil {
+			return nil, fmt.Errorf("The value of %v isn't valid JSON: %v", setting, err.Error())
+		}
+	}
+
+	settings := map[string]interface{}{names[len(names)-1]: value}
+	for i := len(names) - 2; i >= 0; i-- {
+		settings = map[string]interface{}{names[i]: settings}
+	}
+
+	return settings, nil
+}
+
+// saveConfigFile writes the merged config to the config file as the next version of the config, and saves that
+// version in the database.
+func saveConfigFile(command *cobra.Command, config *model.Config, merged *model.Config, filePath string) error {
+	// The database is connected to before the file is written so that the version of the config can be saved
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	merged.ConfigVersion = model.NewInt64(*config.ConfigVersion + 1)
+
+	if appErr := utils.SaveConfig(filePath, merged); appErr != nil {
+		return appErr
+	}
+
+	if _, appErr := a.RecordConfigHistory(config, merged, "", model.CONFIGURATION_HISTORY_SOURCE_CLI); appErr != nil {
+		return errors.New("The config was saved, but its version couldn't be. Error: " + appErr.Error())
+	}
+
+	return nil
+}
+
+// mergeConfigFile applies the settings in the file at path onto the config, and returns the result along with what it
+// changes once they've been confirmed. No changes are returned if the config already has the settings.
+func mergeConfigFile(command *cobra.Command, config *model.Config, path string, target string) (*model.Config, []*model.ConfigChange, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	return mergeConfigSettings(command, config, file, target, true)
+}
+
+// mergeConfigSettings applies the settings in data onto the config and shows what that changes, asking for the changes
+// to be confirmed first if askToConfirm is set and --yes isn't given.
+func mergeConfigSettings(command *cobra.Command, config *model.Config, data io.Reader, target string, askToConfirm bool) (*model.Config, []*model.ConfigChange, error) {
+	merged, appErr := model.MergeConfigJson(config, data)
+	if appErr != nil {
+		return nil, nil, appErr
+	}
+	merged.SetDefaults()
+
+	if appErr := merged.IsValid(); appErr != nil {
+		return nil, nil, appErr
+	}
+
+	changes := model.DiffConfigs(config, merged)
+	if len(changes) == 0 {
+		CommandPrettyPrintln("The config already has these settings")
+		return merged, nil, nil
+	}
+
+	for _, change := range changes {
+		CommandPrettyPrintln(change.String())
+	}
+
+	if yes, _ := command.Flags().GetBool("yes"); askToConfirm && !yes {
+		var confirm string
+		CommandPrettyPrintln(fmt.Sprintf("Are you sure you want to change these %v settings in %v? (YES/NO): ", len(changes), target))
+		fmt.Scanln(&confirm)
+		if confirm != "YES" {
+			return nil, nil, errors.New("ABORTED: You did not answer YES exactly, in all capitals.")
+		}
+	}
+
+	return merged, changes, nil
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
 )
 
 func TestConfigValidate(t *testing.T) {
@@ -28,3 +29,75 @@ func TestConfigValidate(t *testing.T) {
 	assert.Error(t, RunCommand(t, "--config", "foo.json", "config", "validate"))
 	assert.NoError(t, RunCommand(t, "--config", path, "config", "validate"))
 }
+
+func TestConfigExportImport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	sourcePath := filepath.Join(dir, "source.json")
+	source := &model.Config{}
+	source.SetDefaults()
+	source.TeamSettings.SiteName = "Promoted"
+	*source.SqlSettings.DataSource = "source-datasource"
+	require.NoError(t, ioutil.WriteFile(sourcePath, []byte(source.ToJson()), 0600))
+
+	targetPath := filepath.Join(dir, "target.json")
+	target := &model.Config{}
+	target.SetDefaults()
+	// The import connects to the target's database to save the version of the config, so its secret is a password
+	target.EmailSettings.SMTPPassword = "target-password"
+	require.NoError(t, ioutil.WriteFile(targetPath, []byte(target.ToJson()), 0600))
+
+	exportPath := filepath.Join(dir, "settings.json")
+	CheckCommand(t, "--config", sourcePath, "config", "export", "--exclude-secrets", "-o", exportPath)
+
+	exported, err := ioutil.ReadFile(exportPath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(exported), "source-datasource")
+
+	assert.Error(t, RunCommand(t, "--config", targetPath, "config", "import", exportPath))
+
+	output := CheckCommand(t, "--config", targetPath, "config", "import", exportPath, "--merge", "--yes")
+	assert.Contains(t, output, "TeamSettings.SiteName")
+	assert.NotContains(t, output, "target-password")
+
+	imported, _, err := utils.ReadConfigFile(targetPath, false)
+	require.NoError(t, err)
+	assert.Equal(t, "Promoted", imported.TeamSettings.SiteName)
+	assert.Equal(t, "target-password", imported.EmailSettings.SMTPPassword)
+	assert.Equal(t, target.SqlSettings.AtRestEncryptKey, imported.SqlSettings.AtRestEncryptKey)
+
+	unknownPath := filepath.Join(dir, "unknown.json")
+	require.NoError(t, ioutil.WriteFile(unknownPath, []byte(`{"TeamSettings": {"NotASetting": true}}`), 0600))
+	assert.Error(t, RunCommand(t, "--config", targetPath, "config", "import", unknownPath, "--merge", "--yes"))
+}
+
+func TestConfigSet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.json")
+	config := &model.Config{}
+	config.SetDefaults()
+	config.EmailSettings.SMTPPassword = "password"
+	require.NoError(t, ioutil.WriteFile(path, []byte(config.ToJson()), 0600))
+
+	CheckCommand(t, "--config", path, "config", "set", "TeamSettings.SiteName", "Changed")
+	CheckCommand(t, "--config", path, "config", "set", "TeamSettings.MaxUsersPerTeam", "42")
+	CheckCommand(t, "--config", path, "config", "set", "ServiceSettings.DisabledAPIEndpoints", "POST /api/v4/users", "POST /api/v4/teams")
+
+	saved, _, err := utils.ReadConfigFile(path, false)
+	require.NoError(t, err)
+	assert.Equal(t, "Changed", saved.TeamSettings.SiteName)
+	assert.Equal(t, 42, *saved.TeamSettings.MaxUsersPerTeam)
+	assert.Equal(t, []string{"POST /api/v4/users", "POST /api/v4/teams"}, saved.ServiceSettings.DisabledAPIEndpoints)
+	assert.Equal(t, "password", saved.EmailSettings.SMTPPassword)
+	assert.Equal(t, *config.ConfigVersion+3, *saved.ConfigVersion)
+
+	assert.Error(t, RunCommand(t, "--config", path, "config", "set", "TeamSettings.NotASetting", "true"))
+	assert.Error(t, RunCommand(t, "--config", path, "config", "set", "TeamSettings", "true"))
+	assert.Error(t, RunCommand(t, "--config", path, "config", "set", "TeamSettings.MaxUsersPerTeam", "many"))
+	assert.Error(t, RunCommand(t, "--config", path, "config", "set", "TeamSettings.MaxUsersPerTeam", "0"))
+}
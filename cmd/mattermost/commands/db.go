@@ -0,0 +1,377 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/app"
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store/sqlstore"
+	"github.com/mattermost/mattermost-server/utils"
+	"github.com/spf13/cobra"
+)
+
+var DbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Management of the database",
+}
+
+var DbDoctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the database schema",
+	Long: `Compare the tables, columns and indexes in the database against the ones that the server expects, and report every difference between them, along with tables and columns that don't use utf8mb4 on MySQL. The database isn't upgraded first, so this can be used to check it after an upgrade failed.
+
+With --fix, missing tables and indexes are created. Indexes are built without locking their tables against writes where the database supports it. Other differences need to be fixed by hand.`,
+	Example: "  db doctor --fix",
+	RunE:    dbDoctorCmdF,
+}
+
+var DbAnalyzeCmd = &cobra.Command{
+	Use:   "analyze",
+	Short: "Suggest missing indexes",
+	Long: `Explain the queries that the server runs most often, such as loading the posts of a channel or the channels of a user, and report whether the database has to read whole tables or sort rows itself to run them along with how many rows it expects to read. For each query that no index fits, the statement that creates the index it needs is suggested.
+
+Nothing is changed in the database. Small tables are often read in full even when there's an index that fits, so suggestions are only made for indexes that are missing.`,
+	Example: "  db analyze --output json",
+	RunE:    dbAnalyzeCmdF,
+}
+
+var DbMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Migrate the database",
+	Long: `Create and upgrade the database schema, and migrate the roles and permissions in it, to what this version of the server needs and then exit. This lets the database be migrated before the servers are started, such as by a job that runs ahead of a rolling upgrade. Servers that have ServiceSettings.SkipMigrations set don't migrate the database themselves, and won't start until this has been run.
+
+Only one server or command migrates the database at a time, so this waits for any other that already is to finish first.`,
+	Example: "  db migrate",
+	RunE:    dbMigrateCmdF,
+}
+
+var DbMigrateCharsetCmd = &cobra.Command{
+	Use:   "migrate-charset",
+	Short: "Convert a MySQL database to utf8mb4",
+	Long: `Convert the tables of a MySQL database that was created with the utf8 charset to utf8mb4, so that messages can contain emoji. Each table is converted in turn, and tables that have already been converted are skipped, so the command can be run again if it's interrupted. Tables are locked against writes while they're converted, so this should be done while the server is stopped.
+
+Back up the database before running this, and confirm that it was backed up with --confirm-backup.`,
+	Example: "  db migrate-charset --to utf8mb4 --confirm-backup",
+	RunE:    dbMigrateCharsetCmdF,
+}
+
+var DbPurgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Delete old sessions and audits",
+	Long: `Delete the sessions that expired more than --sessions-retention-days ago, the sessions of users that have been deleted or deactivated, and the audits created more than --audits-retention-days ago. Rows are deleted in batches of --batch-size so that the database can keep serving the server while this runs.
+
+Sessions and audits are only purged if their retention is given. A retention of 0 days deletes every expired session, or every audit.`,
+	Example: "  db purge --sessions-retention-days 30 --audits-retention-days 365",
+	RunE:    dbPurgeCmdF,
+}
+
+var DbTrimCmd = &cobra.Command{
+	Use:   "trim",
+	Short: "Trim the audits or jobs tables",
+	Long: `Delete the audits or jobs that are older than their retention days, or that aren't among the newest rows up to their maximum number of rows, as set in the service settings. Rows are deleted in batches of the table trim batch size. Jobs that are pending or in progress are never deleted.
+
+With --dry-run, only how many rows would be deleted is shown.`,
+	Example: "  db trim --table jobs --dry-run",
+	RunE:    dbTrimCmdF,
+}
+
+func init() {
+	DbDoctorCmd.Flags().Bool("fix", false, "Create missing tables and indexes.")
+
+	AddOutputFlag(DbAnalyzeCmd)
+
+	DbMigrateCharsetCmd.Flags().String("to", "", "The charset to convert to. Only utf8mb4 is supported.")
+	DbMigrateCharsetCmd.Flags().Bool("confirm-backup", false, "Confirm that the database was backed up recently.")
+
+	DbPurgeCmd.Flags().Int("sessions-retention-days", -1, "Purge sessions that expired more than this many days ago.")
+	DbPurgeCmd.Flags().Int("audits-retention-days", -1, "Purge audits created more than this many days ago.")
+	DbPurgeCmd.Flags().Int("batch-size", 1000, "How many rows to delete at a time.")
+
+	DbTrimCmd.Flags().String("table", "", "The table to trim: audits or jobs.")
+	DbTrimCmd.Flags().Bool("dry-run", false, "Show how many rows would be deleted without deleting them.")
+	AddOutputFlag(DbTrimCmd)
+
+	DbCmd.AddCommand(
+		DbDoctorCmd,
+		DbAnalyzeCmd,
+		DbMigrateCmd,
+		DbMigrateCharsetCmd,
+		DbPurgeCmd,
+		DbTrimCmd,
+	)
+	RootCmd.AddCommand(DbCmd)
+}
+
+// initSchemaCheckSupplier connects to the database without upgrading it or creating anything in it, so that it can be
+// checked or converted as it is.
+func initSchemaCheckSupplier(command *cobra.Command) (*sqlstore.SqlSupplier, error) {
+	if err := utils.TranslationsPreInit(); err != nil {
+		return nil, err
+	}
+	model.AppErrorInit(utils.T)
+
+	configFile, err := command.Flags().GetString("config")
+	if err != nil {
+		return nil, err
+	}
+
+	config, _, _, appErr := utils.LoadConfig(configFile)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	return sqlstore.NewSqlSupplierForSchemaCheck(config.SqlSettings), nil
+}
+
+func dbDoctorCmdF(command *cobra.Command, args []string) error {
+	fix, _ := command.Flags().GetBool("fix")
+
+	supplier, err := initSchemaCheckSupplier(command)
+	if err != nil {
+		return err
+	}
+	defer supplier.Close()
+
+	issues, err := supplier.CheckSchema()
+	if err != nil {
+		return err
+	}
+
+	if fix {
+		fixed, err := supplier.RepairSchema(issues)
+		for _, issue := range fixed {
+			CommandPrettyPrintln("Fixed " + issue.String())
+		}
+		if err != nil {
+			return err
+		}
+
+		if issues, err = supplier.CheckSchema(); err != nil {
+			return err
+		}
+	}
+
+	if len(issues) == 0 {
+		CommandPrettyPrintln("The database schema is correct")
+		return nil
+	}
+
+	for _, issue := range issues {
+		if issue.Fixable {
+			CommandPrettyPrintln(issue.String() + " [fixable with --fix]")
+		} else {
+			CommandPrettyPrintln(issue.String())
+		}
+	}
+
+	return fmt.Errorf("The database schema has %v problems", len(issues))
+}
+
+func dbAnalyzeCmdF(command *cobra.Command, args []string) error {
+	printer, err := NewPrinter(command)
+	if err != nil {
+		return err
+	}
+
+	supplier, err := initSchemaCheckSupplier(command)
+	if err != nil {
+		return err
+	}
+	defer supplier.Close()
+
+	advice, err := supplier.AnalyzeIndexes()
+	if err != nil {
+		return err
+	}
+
+	if err := printer.PrintRecords(advice); err != nil {
+		return err
+	}
+
+	missing := 0
+	for _, queryAdvice := range advice {
+		if queryAdvice.MissingIndex {
+			missing++
+		}
+	}
+
+	if missing == 0 {
+		printer.PrintMessage("No indexes are missing")
+	} else {
+		printer.PrintMessage(fmt.Sprintf("%v indexes are missing", missing))
+	}
+
+	return nil
+}
+
+func dbMigrateCmdF(command *cobra.Command, args []string) error {
+	if err := utils.TranslationsPreInit(); err != nil {
+		return err
+	}
+	model.AppErrorInit(utils.T)
+
+	configFile, err := command.Flags().GetString("config")
+	if err != nil {
+		return err
+	}
+
+	config, _, _, appErr := utils.LoadConfig(configFile)
+	if appErr != nil {
+		return appErr
+	}
+
+	// The schema is migrated before the app is set up, since the app won't start without it being migrated if the
+	// config skips migrations
+	sqlstore.NewSqlSupplier(config.SqlSettings, nil, nil).Close()
+	CommandPrettyPrintln("Migrated the database schema to version " + model.CurrentVersion)
+
+	a, err := InitDBCommandContext(configFile)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	if err := doAdvancedPermissionsMigration(a); err != nil {
+		return err
+	}
+	if err := checkAdvancedMigrations(a); err != nil {
+		return err
+	}
+
+	CommandPrettyPrintln("Migrated the roles and permissions")
+	return nil
+}
+
+// checkAdvancedMigrations returns an error if any of the migrations of roles and permissions haven't been completed.
+func checkAdvancedMigrations(a *app.App) error {
+	pending, appErr := a.GetPendingAdvancedMigrations()
+	if appErr != nil {
+		return appErr
+	}
+
+	if len(pending) > 0 {
+		return fmt.Errorf("The migrations %v haven't been completed. Run the db migrate command to complete them.", strings.Join(pending, ", "))
+	}
+
+	return nil
+}
+
+func dbMigrateCharsetCmdF(command *cobra.Command, args []string) error {
+	to, _ := command.Flags().GetString("to")
+	if to != "utf8mb4" {
+		return errors.New("Only --to utf8mb4 is supported")
+	}
+
+	if confirmed, _ := command.Flags().GetBool("confirm-backup"); !confirmed {
+		return errors.New("Back up the database before converting it, and then run this again with --confirm-backup")
+	}
+
+	supplier, err := initSchemaCheckSupplier(command)
+	if err != nil {
+		return err
+	}
+	defer supplier.Close()
+
+	warnings, err := supplier.CheckCharsetSettings()
+	if err != nil {
+		return err
+	}
+
+	for _, warning := range warnings {
+		CommandPrettyPrintln("Warning: " + warning)
+	}
+
+	err = supplier.MigrateCharset(func(table string, done int, total int, converted bool) {
+		if converted {
+			CommandPrettyPrintln(fmt.Sprintf("Converting %v (%v/%v)", table, done, total))
+		} else {
+			CommandPrettyPrintln(fmt.Sprintf("Skipping %v (%v/%v), it's already converted", table, done, total))
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	CommandPrettyPrintln("The database now uses utf8mb4. Restart the server for it to stop warning about emoji.")
+	return nil
+}
+
+func dbPurgeCmdF(command *cobra.Command, args []string) error {
+	sessionsRetentionDays, _ := command.Flags().GetInt("sessions-retention-days")
+	auditsRetentionDays, _ := command.Flags().GetInt("audits-retention-days")
+	if sessionsRetentionDays < 0 && auditsRetentionDays < 0 {
+		return errors.New("Give --sessions-retention-days, --audits-retention-days or both")
+	}
+
+	batchSize, _ := command.Flags().GetInt("batch-size")
+	if batchSize <= 0 {
+		return errors.New("--batch-size must be a positive number")
+	}
+
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	if sessionsRetentionDays >= 0 {
+		expired, appErr := a.PurgeExpiredSessions(retentionCutoff(sessionsRetentionDays), batchSize)
+		if appErr != nil {
+			return appErr
+		}
+
+		orphaned, appErr := a.PurgeOrphanedSessions(batchSize)
+		if appErr != nil {
+			return appErr
+		}
+
+		CommandPrettyPrintln(fmt.Sprintf("Purged %v expired sessions and %v sessions of deleted or deactivated users", expired, orphaned))
+	}
+
+	if auditsRetentionDays >= 0 {
+		deleted, appErr := a.PurgeAudits(retentionCutoff(auditsRetentionDays), batchSize)
+		if appErr != nil {
+			return appErr
+		}
+
+		CommandPrettyPrintln(fmt.Sprintf("Purged %v audits", deleted))
+	}
+
+	return nil
+}
+
+func dbTrimCmdF(command *cobra.Command, args []string) error {
+	printer, err := NewPrinter(command)
+	if err != nil {
+		return err
+	}
+
+	table, _ := command.Flags().GetString("table")
+	if table != app.TRIM_TABLE_AUDITS && table != app.TRIM_TABLE_JOBS {
+		return errors.New("--table must be " + app.TRIM_TABLE_AUDITS + " or " + app.TRIM_TABLE_JOBS)
+	}
+
+	dryRun, _ := command.Flags().GetBool("dry-run")
+
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	trim, appErr := a.TrimTable(table, dryRun)
+	if appErr != nil {
+		return appErr
+	}
+
+	return printer.PrintRecord(trim)
+}
+
+func retentionCutoff(days int) int64 {
+	return model.GetMillis() - int64(days)*24*60*60*1000
+}
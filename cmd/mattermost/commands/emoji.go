@@ -0,0 +1,110 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var EmojiCmd = &cobra.Command{
+	Use:   "emoji",
+	Short: "Management of custom emoji",
+}
+
+var EmojiImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import custom emoji",
+	Long: `Create custom emoji from the PNG and GIF files in a directory, such as an emoji pack exported from another service.
+Each emoji is named after its file without the extension.`,
+	Example: "  emoji import --dir ./pack --creator user1 --skip",
+	RunE:    emojiImportCmdF,
+}
+
+func init() {
+	EmojiImportCmd.Flags().String("dir", "", "Directory of PNG and GIF files")
+	EmojiImportCmd.Flags().String("creator", "", "Username or email of the user who the emoji are created by")
+	EmojiImportCmd.Flags().Bool("overwrite", false, "Replace existing emoji that have the same name")
+	EmojiImportCmd.Flags().Bool("skip", false, "Leave existing emoji that have the same name")
+
+	EmojiCmd.AddCommand(
+		EmojiImportCmd,
+	)
+	RootCmd.AddCommand(EmojiCmd)
+}
+
+func emojiImportCmdF(command *cobra.Command, args []string) error {
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	dir, _ := command.Flags().GetString("dir")
+	if dir == "" {
+		return errors.New("Directory is required")
+	}
+
+	creatorArg, _ := command.Flags().GetString("creator")
+	creator := getUserFromUserArg(a, creatorArg)
+	if creator == nil {
+		return errors.New("Unable to find creator '" + creatorArg + "'")
+	}
+
+	overwrite, _ := command.Flags().GetBool("overwrite")
+	skip, _ := command.Flags().GetBool("skip")
+	if overwrite && skip {
+		return errors.New("Only one of --overwrite and --skip can be used")
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	imported, skipped, failed := 0, 0, 0
+	for _, file := range files {
+		ext := strings.ToLower(filepath.Ext(file.Name()))
+		if file.IsDir() || (ext != ".png" && ext != ".gif") {
+			continue
+		}
+
+		name := strings.ToLower(strings.TrimSuffix(file.Name(), filepath.Ext(file.Name())))
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			CommandPrintErrorln("Unable to read '" + file.Name() + "'. Error: " + err.Error())
+			failed++
+			continue
+		}
+
+		if _, appErr := a.ImportEmoji(creator.Id, name, file.Name(), data, overwrite); appErr != nil {
+			if skip && appErr.Id == "api.emoji.create.duplicate.app_error" {
+				CommandPrettyPrintln("Skipped '" + name + "' since it already exists")
+				skipped++
+				continue
+			}
+
+			CommandPrintErrorln("Unable to import '" + file.Name() + "'. Error: " + appErr.Error())
+			failed++
+			continue
+		}
+
+		CommandPrettyPrintln("Imported '" + name + "'")
+		imported++
+	}
+
+	CommandPrettyPrintln(fmt.Sprintf("Imported %v, skipped %v and failed to import %v emoji", imported, skipped, failed))
+
+	if failed > 0 {
+		return errors.New("Some emoji couldn't be imported")
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/api4"
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+func TestEmojiImport(t *testing.T) {
+	th := api4.Setup().InitBasic()
+	defer th.TearDown()
+
+	th.App.UpdateConfig(func(cfg *model.Config) { *cfg.ServiceSettings.EnableCustomEmoji = true })
+
+	utils.TranslationsPreInit()
+	config, _, _, loadErr := utils.LoadConfig("config.json")
+	require.Nil(t, loadErr)
+	config.ServiceSettings.EnableCustomEmoji = model.NewBool(true)
+
+	dir, err := ioutil.TempDir("", "emoji")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	configPath := filepath.Join(dir, "config.json")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(config.ToJson()), 0600))
+
+	name1 := "cmd" + model.NewId()
+	name2 := "cmd" + model.NewId()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name1+".png"), utils.CreateTestPng(t, 10, 10), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name2+".gif"), utils.CreateTestGif(t, 10, 10), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "readme.txt"), []byte("not an emoji"), 0600))
+
+	CheckCommand(t, "--config", configPath, "emoji", "import", "--dir", dir, "--creator", th.BasicUser.Email)
+
+	emoji1, appErr := th.App.GetEmojiByName(name1)
+	require.Nil(t, appErr)
+	_, appErr = th.App.GetEmojiByName(name2)
+	require.Nil(t, appErr)
+	_, appErr = th.App.GetEmojiByName("readme")
+	assert.NotNil(t, appErr)
+
+	// the emoji already exist
+	require.Error(t, RunCommand(t, "--config", configPath, "emoji", "import", "--dir", dir, "--creator", th.BasicUser.Email))
+
+	CheckCommand(t, "--config", configPath, "emoji", "import", "--dir", dir, "--creator", th.BasicUser.Email, "--skip")
+
+	emoji, appErr := th.App.GetEmojiByName(name1)
+	require.Nil(t, appErr)
+	assert.Equal(t, emoji1.Id, emoji.Id)
+
+	CheckCommand(t, "--config", configPath, "emoji", "import", "--dir", dir, "--creator", th.BasicUser.Email, "--overwrite")
+
+	emoji, appErr = th.App.GetEmojiByName(name1)
+	require.Nil(t, appErr)
+	assert.NotEqual(t, emoji1.Id, emoji.Id)
+
+	require.Error(t, RunCommand(t, "--config", configPath, "emoji", "import", "--dir", dir, "--creator", th.BasicUser.Email, "--skip", "--overwrite"))
+	require.Error(t, RunCommand(t, "--config", configPath, "emoji", "import", "--creator", th.BasicUser.Email))
+}
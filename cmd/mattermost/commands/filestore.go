@@ -0,0 +1,70 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"errors"
+
+	"github.com/mattermost/mattermost-server/app"
+	"github.com/spf13/cobra"
+)
+
+var FileStoreCmd = &cobra.Command{
+	Use:   "filestore",
+	Short: "Management of the file store",
+}
+
+var FileStoreVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the file store against the file infos",
+	Long: `Check that the file of every file info is in the file store, and that every object in the directories that files are uploaded to is the file, thumbnail or preview of a file info. Directories from the last day are skipped, since files that are still being uploaded don't have file infos yet.
+
+With --repair, the files that are missing are marked as missing so that clients show a placeholder for them instead of a broken download, and the objects that are dangling are deleted.
+
+The same check can be run by creating a file_store_verification job, with "repair": "true" in its data to repair what's found.`,
+	Example: "  filestore verify --repair",
+	RunE:    fileStoreVerifyCmdF,
+}
+
+func init() {
+	FileStoreVerifyCmd.Flags().Bool("repair", false, "Mark the missing files and delete the dangling objects.")
+	FileStoreVerifyCmd.Flags().Int("concurrency", app.FILE_STORE_VERIFICATION_CONCURRENCY, "How many files to check for in the file store at once.")
+	AddOutputFlag(FileStoreVerifyCmd)
+
+	FileStoreCmd.AddCommand(
+		FileStoreVerifyCmd,
+	)
+	RootCmd.AddCommand(FileStoreCmd)
+}
+
+func fileStoreVerifyCmdF(command *cobra.Command, args []string) error {
+	printer, err := NewPrinter(command)
+	if err != nil {
+		return err
+	}
+
+	repair, _ := command.Flags().GetBool("repair")
+	concurrency, _ := command.Flags().GetInt("concurrency")
+	if concurrency < 1 {
+		return errors.New("--concurrency must be at least 1")
+	}
+
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	// What was found before any error is still printed
+	result, appErr := a.VerifyFileStore(repair, concurrency, nil)
+	if err := printer.PrintRecord(result); err != nil {
+		return err
+	}
+
+	if appErr != nil {
+		return appErr
+	}
+
+	return nil
+}
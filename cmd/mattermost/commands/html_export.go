@@ -0,0 +1,73 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var HtmlExportCmd = &cobra.Command{
+	Use:   "html",
+	Short: "Export a channel as a static HTML archive",
+	Long: `Export the posts in a channel as HTML pages that can be browsed without a server, with a page for each month and an index listing the months and the people who posted.
+Files attached to the posts are copied into the archive along with their thumbnails. Deleted posts aren't exported.`,
+	Example: "export html --channel myteam:mychannel --output archive/",
+	RunE:    htmlExportCmdF,
+}
+
+func init() {
+	HtmlExportCmd.Flags().String("channel", "", "The channel to export, given as team:channel or as the channel's id.")
+	HtmlExportCmd.Flags().StringP("output", "o", "", "The directory to write the archive to.")
+	HtmlExportCmd.Flags().String("timezone", "UTC", "The timezone to use for the times of posts and to split them into months, such as America/New_York.")
+	MessageExportCmd.AddCommand(HtmlExportCmd)
+}
+
+func htmlExportCmdF(command *cobra.Command, args []string) error {
+	channelArg, err := command.Flags().GetString("channel")
+	if err != nil || channelArg == "" {
+		return errors.New("channel flag error")
+	}
+
+	output, err := command.Flags().GetString("output")
+	if err != nil || output == "" {
+		return errors.New("output flag error")
+	}
+
+	timezone, err := command.Flags().GetString("timezone")
+	if err != nil {
+		return errors.New("timezone flag error")
+	}
+
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return errors.New("timezone must be a timezone name such as UTC or America/New_York")
+	}
+
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	channel := getChannelFromChannelArg(a, channelArg)
+	if channel == nil {
+		return errors.New("Unable to find channel '" + channelArg + "'")
+	}
+
+	export, appErr := a.ExportChannelHtml(channel, output, location)
+	if appErr != nil {
+		return appErr
+	}
+
+	CommandPrettyPrintln(fmt.Sprintf("Exported %v posts from %v months with %v files to %v", export.PostCount, len(export.Months), export.FileCount, output))
+	if export.MissingFileCount > 0 {
+		CommandPrettyPrintln(fmt.Sprintf("%v files attached to the posts couldn't be found", export.MissingFileCount))
+	}
+
+	return nil
+}
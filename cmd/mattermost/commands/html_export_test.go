@@ -0,0 +1,17 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHtmlExportInvalidFlags(t *testing.T) {
+	// these should all fail fast without needing the database
+	require.Error(t, RunCommand(t, "export", "html", "--output", "out"))
+	require.Error(t, RunCommand(t, "export", "html", "--channel", "team:channel"))
+	require.Error(t, RunCommand(t, "export", "html", "--channel", "team:channel", "--output", "out", "--timezone", "Mars/Olympus_Mons"))
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+	"github.com/spf13/cobra"
+)
+
+var I18nCmd = &cobra.Command{
+	Use:   "i18n",
+	Short: "Translations",
+}
+
+var I18nCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Report missing translations",
+	Long: `Compare each of the server's bundled locales to English and report the messages that they haven't translated. Users are shown those messages in the language that their locale is a variant of, if it's bundled, or otherwise in English.
+
+With --verbose, the ids of the missing messages are listed too.`,
+	Example: "  i18n check --locale pt-BR --verbose",
+	RunE:    i18nCheckCmdF,
+}
+
+func init() {
+	I18nCheckCmd.Flags().String("locale", "", "Only check this locale.")
+	I18nCheckCmd.Flags().Bool("verbose", false, "List the ids of the missing messages.")
+	AddOutputFlag(I18nCheckCmd)
+
+	I18nCmd.AddCommand(
+		I18nCheckCmd,
+	)
+	RootCmd.AddCommand(I18nCmd)
+}
+
+type missingTranslations struct {
+	Locale  string   `json:"locale"`
+	Missing int      `json:"missing"`
+	Ids     []string `json:"ids,omitempty"`
+}
+
+func (m *missingTranslations) String() string {
+	description := fmt.Sprintf("%v: %v missing", m.Locale, m.Missing)
+	for _, id := range m.Ids {
+		description += "\n  " + id
+	}
+	return description
+}
+
+func i18nCheckCmdF(command *cobra.Command, args []string) error {
+	printer, err := NewPrinter(command)
+	if err != nil {
+		return err
+	}
+
+	if err := utils.TranslationsPreInit(); err != nil {
+		return err
+	}
+
+	onlyLocale, _ := command.Flags().GetString("locale")
+	verbose, _ := command.Flags().GetBool("verbose")
+
+	missing, err := utils.MissingTranslations()
+	if err != nil {
+		return err
+	}
+
+	var locales []string
+	for locale := range utils.GetSupportedLocales() {
+		if locale == model.DEFAULT_LOCALE {
+			continue
+		}
+
+		if onlyLocale == "" || strings.EqualFold(locale, onlyLocale) {
+			locales = append(locales, locale)
+		}
+	}
+
+	if onlyLocale != "" && len(locales) == 0 {
+		return errors.New("Unable to find a bundled locale other than English named " + onlyLocale + ".")
+	}
+	sort.Strings(locales)
+
+	records := make([]*missingTranslations, 0, len(locales))
+	for _, locale := range locales {
+		record := &missingTranslations{Locale: locale, Missing: len(missing[locale])}
+		if verbose {
+			record.Ids = missing[locale]
+		}
+		records = append(records, record)
+	}
+
+	return printer.PrintRecords(records)
+}
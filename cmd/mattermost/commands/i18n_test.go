@@ -0,0 +1,23 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestI18nCheck(t *testing.T) {
+	output := CheckCommand(t, "i18n", "check")
+	assert.Contains(t, output, "pt-BR: ")
+	assert.NotContains(t, output, "en: ")
+	assert.NotContains(t, output, "app.reaction.save_reaction.disabled.app_error")
+
+	output = CheckCommand(t, "i18n", "check", "--locale", "pt-BR", "--verbose")
+	assert.Contains(t, output, "app.reaction.save_reaction.disabled.app_error")
+	assert.NotContains(t, output, "de: ")
+
+	assert.Error(t, RunCommand(t, "i18n", "check", "--locale", "en"))
+}
@@ -6,6 +6,7 @@ package commands
 import (
 	"github.com/mattermost/mattermost-server/app"
 	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store/sqlstore"
 	"github.com/mattermost/mattermost-server/utils"
 	"github.com/spf13/cobra"
 )
@@ -22,7 +23,9 @@ func InitDBCommandContextCobra(command *cobra.Command) (*app.App, error) {
 		panic(err)
 	}
 
-	a.DoAdvancedPermissionsMigration()
+	if err := doAdvancedPermissionsMigration(a); err != nil {
+		panic(err)
+	}
 
 	return a, nil
 }
@@ -44,3 +47,19 @@ func InitDBCommandContext(configFileLocation string) (*app.App, error) {
 
 	return a, nil
 }
+
+// doAdvancedPermissionsMigration migrates the roles and permissions while holding the migration lock, so that they
+// aren't migrated at the same time by a starting server, another command or db migrate.
+func doAdvancedPermissionsMigration(a *app.App) error {
+	lockSupplier := sqlstore.NewSqlSupplierForSchemaCheck(a.Config().SqlSettings)
+	defer lockSupplier.Close()
+
+	unlock, err := lockSupplier.LockMigrations()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	a.DoAdvancedPermissionsMigration()
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// LOCAL_MODE_ANNOTATION marks the commands that can be run with --local.
+	LOCAL_MODE_ANNOTATION = "local_mode"
+
+	// LOCAL_MODE_PAGE_SIZE is how many of a list are fetched at a time by local commands that list everything.
+	LOCAL_MODE_PAGE_SIZE = 200
+)
+
+type localCommandFunc func(client *model.Client4, command *cobra.Command, args []string) error
+
+func init() {
+	RootCmd.PersistentFlags().Bool("local", false, "Run the command through the running server over its local mode socket instead of connecting to the database. The server must have ServiceSettings.EnableLocalMode set.")
+	RootCmd.PersistentPreRunE = checkLocalModeSupported
+}
+
+func checkLocalModeSupported(command *cobra.Command, args []string) error {
+	if local, _ := command.Flags().GetBool("local"); local && command.Annotations[LOCAL_MODE_ANNOTATION] == "" {
+		return fmt.Errorf("The %v command can't be run with --local", command.CommandPath())
+	}
+
+	return nil
+}
+
+// withLocalMode lets the command be run with --local, in which case localCmdF is run with a client connected to the
+// running server instead of the command's usual RunE.
+func withLocalMode(command *cobra.Command, localCmdF localCommandFunc) {
+	if command.Annotations == nil {
+		command.Annotations = map[string]string{}
+	}
+	command.Annotations[LOCAL_MODE_ANNOTATION] = "true"
+
+	cmdF := command.RunE
+	command.RunE = func(command *cobra.Command, args []string) error {
+		if local, _ := command.Flags().GetBool("local"); !local {
+			return cmdF(command, args)
+		}
+
+		client, err := InitLocalClient(command)
+		if err != nil {
+			return err
+		}
+
+		return localCmdF(client, command, args)
+	}
+}
+
+// InitLocalClient returns a client that makes its requests over the local mode socket set in the config file. The
+// requests are made as a system admin, so no login is needed.
+func InitLocalClient(command *cobra.Command) (*model.Client4, error) {
+	if err := utils.TranslationsPreInit(); err != nil {
+		return nil, err
+	}
+	model.AppErrorInit(utils.T)
+
+	filePath, err := command.Flags().GetString("config")
+	if err != nil {
+		return nil, err
+	}
+
+	filePath = utils.FindConfigFile(filePath)
+	if filePath == "" {
+		return nil, errors.New("Unable to find the config file")
+	}
+
+	// The server applies the environment overrides, so the socket should be looked for where they say too
+	config, _, err := utils.ReadConfigFile(filePath, true)
+	if err != nil {
+		return nil, err
+	}
+	config.SetDefaults()
+
+	socket := *config.ServiceSettings.LocalModeSocketLocation
+
+	// The host is ignored since every connection is made to the socket
+	client := model.NewAPIv4Client("http://_")
+	client.HttpClient = &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+
+	if _, resp := client.GetPing(); resp.Error != nil {
+		return nil, fmt.Errorf("Unable to connect to the server over the local mode socket at %v. Check that the server is running and that ServiceSettings.EnableLocalMode is true. Error: %v", socket, resp.Error.Error())
+	}
+
+	return client, nil
+}
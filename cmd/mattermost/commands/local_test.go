@@ -0,0 +1,120 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+func writeLocalModeConfig(t *testing.T, dir string, config *model.Config) string {
+	*config.ServiceSettings.EnableLocalMode = true
+	*config.ServiceSettings.LocalModeSocketLocation = filepath.Join(dir, "local.socket")
+
+	path := filepath.Join(dir, "config.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(config.ToJson()), 0600))
+
+	return path
+}
+
+func TestLocalModeNotRunning(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	config := &model.Config{}
+	config.SetDefaults()
+	configPath := writeLocalModeConfig(t, dir, config)
+
+	executable, err := os.Executable()
+	require.NoError(t, err)
+
+	output, err := exec.Command(executable, execArgs(t, []string{"--config", configPath, "--local", "team", "list"})...).CombinedOutput()
+	require.Error(t, err)
+	assert.Contains(t, string(output), "Check that the server is running")
+
+	output, err = exec.Command(executable, execArgs(t, []string{"--config", configPath, "--local", "user", "delete", "someone", "--confirm"})...).CombinedOutput()
+	require.Error(t, err)
+	assert.Contains(t, string(output), "can't be run with --local")
+}
+
+func TestLocalMode(t *testing.T) {
+	th := SetupServerTest()
+	defer th.TearDownServerTest()
+
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	config, _, err := utils.ReadConfigFile(th.configPath, false)
+	require.NoError(t, err)
+	configPath := writeLocalModeConfig(t, dir, config)
+
+	interruptChan := make(chan os.Signal, 1)
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- runServer(configPath, th.disableConfigWatch, false, interruptChan)
+	}()
+	defer func() {
+		interruptChan <- syscall.SIGINT
+		require.NoError(t, <-serverErr)
+	}()
+
+	// Wait for the server to start listening
+	for start := time.Now(); ; time.Sleep(100 * time.Millisecond) {
+		if _, err := os.Stat(*config.ServiceSettings.LocalModeSocketLocation); err == nil {
+			break
+		}
+		require.True(t, time.Since(start) < 30*time.Second, "the server didn't start listening on the local mode socket")
+	}
+
+	id := model.NewId()
+	teamName := "local" + id
+	username := "local" + id[:10]
+	email := "local" + id + "@simulator.amazonses.com"
+	channelName := "local" + id
+
+	CheckCommand(t, "--config", configPath, "--local", "team", "create", "--name", teamName, "--display_name", "Local Team")
+	CheckCommand(t, "--config", configPath, "--local", "user", "create", "--username", username, "--email", email, "--password", "Password1@")
+	CheckCommand(t, "--config", configPath, "--local", "team", "add", teamName, email)
+	CheckCommand(t, "--config", configPath, "--local", "channel", "create", "--team", teamName, "--name", channelName, "--display_name", "Local Channel")
+	CheckCommand(t, "--config", configPath, "--local", "channel", "add", teamName+":"+channelName, username)
+
+	output := CheckCommand(t, "--config", configPath, "--local", "user", "search", username)
+	assert.Contains(t, output, "email: "+email)
+
+	output = CheckCommand(t, "--config", configPath, "--local", "team", "list", "--output", "json")
+	var teams []*teamRecord
+	require.NoError(t, json.Unmarshal([]byte(output), &teams))
+	found := false
+	for _, team := range teams {
+		if team.Name == teamName {
+			found = true
+		}
+	}
+	assert.True(t, found, "the new team should be listed")
+
+	CheckCommand(t, "--config", configPath, "--local", "channel", "archive", teamName+":"+channelName)
+	CheckCommand(t, "--config", configPath, "--local", "user", "deactivate", username)
+
+	output = CheckCommand(t, "--config", configPath, "--local", "config", "export")
+	assert.Contains(t, output, model.FAKE_SETTING)
+	assert.NotContains(t, output, *config.SqlSettings.DataSource)
+
+	// Draining is left until last since the server refuses websocket connections from then on
+	output = CheckCommand(t, "--config", configPath, "system", "drain", "--wait")
+	assert.Contains(t, output, "The server has finished draining")
+}
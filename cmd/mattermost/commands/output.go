@@ -4,8 +4,22 @@
 package commands
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	OUTPUT_FORMAT_TEXT  = "text"
+	OUTPUT_FORMAT_TABLE = "table"
+	OUTPUT_FORMAT_JSON  = "json"
 )
 
 func CommandPrintln(a ...interface{}) (int, error) {
@@ -19,3 +33,147 @@ func CommandPrintErrorln(a ...interface{}) (int, error) {
 func CommandPrettyPrintln(a ...interface{}) (int, error) {
 	return fmt.Fprintln(os.Stderr, a...)
 }
+
+// PROGRESS_BAR_WIDTH is how many characters the bar printed by CommandPrintProgress is.
+const PROGRESS_BAR_WIDTH = 40
+
+// CommandPrintProgress prints a progress bar for the percent, from 0 to 100, followed by the status, over whatever
+// progress was printed before. The line is ended once done is true.
+func CommandPrintProgress(percent int64, status string, done bool) {
+	if percent < 0 {
+		percent = 0
+	} else if percent > 100 {
+		percent = 100
+	}
+
+	filled := int(percent * PROGRESS_BAR_WIDTH / 100)
+	fmt.Fprintf(os.Stderr, "\r\033[K[%s%s] %3d%% %s", strings.Repeat("#", filled), strings.Repeat(" ", PROGRESS_BAR_WIDTH-filled), percent, status)
+	if done {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+// Printer prints what list and get commands find in the format given by the --output flag. Records are structs and
+// are printed as they are in JSON, as a table with a column for each of their fields headed by the field's JSON name,
+// or as text using their String method, which is how the commands printed them before they had a choice of format.
+type Printer struct {
+	Format string
+	Out    io.Writer
+}
+
+// AddOutputFlag adds the --output flag that chooses the format of a list or get command. It's added to each of those
+// commands rather than to the root command since the export commands already use --output for the file they write.
+func AddOutputFlag(command *cobra.Command) {
+	command.Flags().String("output", OUTPUT_FORMAT_TEXT, "Output format: "+OUTPUT_FORMAT_TEXT+", "+OUTPUT_FORMAT_TABLE+" or "+OUTPUT_FORMAT_JSON+".")
+}
+
+func NewPrinter(command *cobra.Command) (*Printer, error) {
+	format, err := command.Flags().GetString("output")
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case OUTPUT_FORMAT_TEXT, OUTPUT_FORMAT_TABLE, OUTPUT_FORMAT_JSON:
+	default:
+		return nil, errors.New("Output format must be one of " + OUTPUT_FORMAT_TEXT + ", " + OUTPUT_FORMAT_TABLE + " or " + OUTPUT_FORMAT_JSON + ".")
+	}
+
+	return &Printer{Format: format, Out: os.Stdout}, nil
+}
+
+// PrintRecords prints a slice of records. An empty slice is printed as an empty JSON array or a table with only its
+// header.
+func (p *Printer) PrintRecords(records interface{}) error {
+	value := reflect.ValueOf(records)
+	if value.Kind() != reflect.Slice {
+		return errors.New("records must be a slice")
+	}
+
+	switch p.Format {
+	case OUTPUT_FORMAT_JSON:
+		return p.printJson(records)
+	case OUTPUT_FORMAT_TABLE:
+		return p.printTable(value.Type().Elem(), value)
+	}
+
+	for i := 0; i < value.Len(); i++ {
+		CommandPrettyPrintln(fmt.Sprint(value.Index(i).Interface()))
+	}
+	return nil
+}
+
+// PrintRecord prints a single record.
+func (p *Printer) PrintRecord(record interface{}) error {
+	switch p.Format {
+	case OUTPUT_FORMAT_JSON:
+		return p.printJson(record)
+	case OUTPUT_FORMAT_TABLE:
+		value := reflect.ValueOf(record)
+		return p.printTable(value.Type(), reflect.Append(reflect.MakeSlice(reflect.SliceOf(value.Type()), 0, 1), value))
+	}
+
+	CommandPrettyPrintln(fmt.Sprint(record))
+	return nil
+}
+
+// PrintMessage prints a line that isn't a record, such as a total. It's left out of JSON so that the output can be
+// parsed.
+func (p *Printer) PrintMessage(message string) {
+	if p.Format != OUTPUT_FORMAT_JSON {
+		CommandPrettyPrintln(message)
+	}
+}
+
+func (p *Printer) printJson(v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(p.Out, string(b))
+	return err
+}
+
+func (p *Printer) printTable(recordType reflect.Type, records reflect.Value) error {
+	for recordType.Kind() == reflect.Ptr {
+		recordType = recordType.Elem()
+	}
+	if recordType.Kind() != reflect.Struct {
+		return errors.New("records must be structs")
+	}
+
+	var fields []int
+	var header []string
+	for i := 0; i < recordType.NumField(); i++ {
+		field := recordType.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if field.PkgPath != "" || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		fields = append(fields, i)
+		header = append(header, strings.ToUpper(strings.Replace(name, "_", " ", -1)))
+	}
+
+	w := tabwriter.NewWriter(p.Out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for i := 0; i < records.Len(); i++ {
+		record := records.Index(i)
+		for record.Kind() == reflect.Ptr {
+			record = record.Elem()
+		}
+
+		row := make([]string, len(fields))
+		for j, field := range fields {
+			row[j] = strings.Replace(fmt.Sprint(record.Field(field).Interface()), "\t", " ", -1)
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestNewPrinter(t *testing.T) {
+	command := &cobra.Command{}
+	AddOutputFlag(command)
+
+	printer, err := NewPrinter(command)
+	require.NoError(t, err)
+	assert.Equal(t, OUTPUT_FORMAT_TEXT, printer.Format)
+
+	for _, format := range []string{OUTPUT_FORMAT_TABLE, OUTPUT_FORMAT_JSON} {
+		require.NoError(t, command.Flags().Set("output", format))
+		printer, err = NewPrinter(command)
+		require.NoError(t, err)
+		assert.Equal(t, format, printer.Format)
+	}
+
+	require.NoError(t, command.Flags().Set("output", "yaml"))
+	_, err = NewPrinter(command)
+	assert.Error(t, err)
+}
+
+func TestPrinterJson(t *testing.T) {
+	var out bytes.Buffer
+	printer := &Printer{Format: OUTPUT_FORMAT_JSON, Out: &out}
+
+	users := []*userRecord{
+		newUserRecord(&model.User{Id: model.NewId(), Username: "user1", Email: "user1@example.com", Roles: model.SYSTEM_USER_ROLE_ID}),
+		newUserRecord(&model.User{Id: model.NewId(), Username: "user2", Email: "user2@example.com", AuthService: model.USER_AUTH_SERVICE_LDAP, DeleteAt: 1}),
+	}
+	require.NoError(t, printer.PrintRecords(users))
+
+	var decoded []map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	require.Len(t, decoded, 2)
+	assert.Equal(t, map[string]interface{}{
+		"id":           users[0].Id,
+		"username":     "user1",
+		"email":        "user1@example.com",
+		"roles":        model.SYSTEM_USER_ROLE_ID,
+		"auth_service": model.USER_AUTH_SERVICE_EMAIL,
+		"status":       "active",
+	}, decoded[0])
+	assert.Equal(t, "deactivated", decoded[1]["status"])
+	assert.Equal(t, model.USER_AUTH_SERVICE_LDAP, decoded[1]["auth_service"])
+
+	out.Reset()
+	require.NoError(t, printer.PrintRecords([]*channelRecord{}))
+	assert.Equal(t, "[]", strings.TrimSpace(out.String()), "an empty list should still be valid JSON")
+
+	out.Reset()
+	require.NoError(t, printer.PrintRecord(&pluginRecord{Id: "testplugin", Version: "0.1.0", Active: true}))
+	var plugin map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &plugin))
+	assert.Equal(t, "testplugin", plugin["id"])
+	assert.Equal(t, true, plugin["active"])
+}
+
+func TestPrinterTable(t *testing.T) {
+	var out bytes.Buffer
+	printer := &Printer{Format: OUTPUT_FORMAT_TABLE, Out: &out}
+
+	team := &model.Team{Name: "team"}
+	channels := []*channelRecord{
+		newChannelRecord(team, &model.Channel{Id: "a", Name: "town-square", DisplayName: "Town Square", Type: model.CHANNEL_OPEN}),
+		newChannelRecord(team, &model.Channel{Id: "a-much-longer-id", Name: "old", DisplayName: "Old\tChannel", Type: model.CHANNEL_PRIVATE, DeleteAt: 1}),
+	}
+	require.NoError(t, printer.PrintRecords(channels))
+
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, []string{"ID", "TEAM", "NAME", "DISPLAY", "NAME", "TYPE", "ARCHIVED"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"a", "team", "town-square", "Town", "Square", "O", "false"}, strings.Fields(lines[1]))
+	assert.Equal(t, []string{"a-much-longer-id", "team", "old", "Old", "Channel", "P", "true"}, strings.Fields(lines[2]))
+
+	// Each column starts at the same place on every line
+	for _, header := range []string{"TEAM", "NAME", "DISPLAY NAME", "TYPE", "ARCHIVED"} {
+		column := strings.Index(lines[0], header)
+		require.True(t, column > 0, header)
+		for _, line := range lines[1:] {
+			assert.NotEqual(t, byte(' '), line[column], "%q should start at column %v in %q", header, column, line)
+			assert.Equal(t, byte(' '), line[column-1], "%q should start at column %v in %q", header, column, line)
+		}
+	}
+
+	out.Reset()
+	require.NoError(t, printer.PrintRecords([]*teamRecord{}))
+	assert.Equal(t, "ID  NAME  DISPLAY NAME  TYPE\n", out.String())
+
+	assert.Error(t, printer.PrintRecords([]string{"not a struct"}))
+	assert.Error(t, printer.PrintRecords(&teamRecord{}))
+}
+
+func TestRecordText(t *testing.T) {
+	user := newUserRecord(&model.User{Id: "userid", Username: "user1", Email: "user1@example.com", DeleteAt: 1})
+	assert.Equal(t, "user1 (user1@example.com) userid, deactivated", user.String())
+
+	channel := newChannelRecord(&model.Team{Name: "team"}, &model.Channel{Name: "old", DeleteAt: 1})
+	assert.Equal(t, "old (archived)", channel.String())
+
+	count := &teamCountRecord{Name: "team", ActiveMemberCount: 3}
+	assert.Equal(t, "team: 3 active members", count.String())
+}
+
+func TestCompletion(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, RootCmd.GenBashCompletion(&out))
+	assert.Contains(t, out.String(), "_mattermost_user_list()")
+	assert.Contains(t, out.String(), "--output=")
+
+	out.Reset()
+	require.NoError(t, RootCmd.GenZshCompletion(&out))
+	assert.Contains(t, out.String(), "#compdef mattermost")
+
+	assert.Error(t, completionCmdF(CompletionCmd, []string{"fish"}))
+	assert.Error(t, completionCmdF(CompletionCmd, []string{}))
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"errors"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/spf13/cobra"
+)
+
+var PluginCmd = &cobra.Command{
+	Use:   "plugin",
+	Short: "Management of plugins",
+}
+
+var PluginAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Install a plugin from a URL",
+	Long: `Download a plugin bundle, check that it has the expected SHA-256 checksum, and install it.
+The download is limited by the InstallFromURL plugin settings, and the server's certificate may be signed by the CA in InstallFromURLCACertificateFile.`,
+	Example: "  plugin add --url https://example.com/plugin.tar.gz --sha256 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08 --activate",
+	RunE:    pluginAddCmdF,
+}
+
+var PluginListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List plugins",
+	Long:    "List the installed plugins and whether they're active.",
+	Example: "  plugin list --output json",
+	RunE:    pluginListCmdF,
+}
+
+func init() {
+	PluginAddCmd.Flags().String("url", "", "URL of the .tar.gz plugin bundle")
+	PluginAddCmd.Flags().String("sha256", "", "Expected SHA-256 checksum of the bundle, in hexadecimal")
+	PluginAddCmd.Flags().Bool("activate", false, "Activate the plugin once it's installed")
+
+	AddOutputFlag(PluginListCmd)
+
+	PluginCmd.AddCommand(
+		PluginAddCmd,
+		PluginListCmd,
+	)
+	RootCmd.AddCommand(PluginCmd)
+}
+
+func pluginAddCmdF(command *cobra.Command, args []string) error {
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	url, _ := command.Flags().GetString("url")
+	if url == "" {
+		return errors.New("URL is required")
+	}
+
+	checksum, _ := command.Flags().GetString("sha256")
+	if checksum == "" {
+		return errors.New("SHA-256 checksum is required")
+	}
+
+	activate, _ := command.Flags().GetBool("activate")
+
+	a.InitPlugins(*a.Config().PluginSettings.Directory, *a.Config().PluginSettings.ClientDirectory, nil)
+
+	manifest, appErr := a.InstallPluginFromURL(&model.InstallPluginFromURLRequest{
+		URL:      url,
+		SHA256:   checksum,
+		Activate: activate,
+	})
+	if appErr != nil {
+		return errors.New("Unable to install plugin. Error: " + appErr.Error())
+	}
+
+	if activate {
+		CommandPrettyPrintln("Installed and activated plugin " + manifest.Id + " " + manifest.Version)
+	} else {
+		CommandPrettyPrintln("Installed plugin " + manifest.Id + " " + manifest.Version)
+	}
+
+	return nil
+}
+
+// pluginRecord is how a plugin is printed by plugin list.
+type pluginRecord struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Active      bool   `json:"active"`
+	Prepackaged bool   `json:"prepackaged"`
+}
+
+func newPluginRecord(plugin *model.PluginInfo, active bool) *pluginRecord {
+	return &pluginRecord{
+		Id:          plugin.Id,
+		Name:        plugin.Name,
+		Version:     plugin.Version,
+		Active:      active,
+		Prepackaged: plugin.Prepackaged,
+	}
+}
+
+func (r *pluginRecord) String() string {
+	if r.Active {
+		return r.Id + " " + r.Version + " (active)"
+	}
+	return r.Id + " " + r.Version
+}
+
+func pluginListCmdF(command *cobra.Command, args []string) error {
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	printer, err := NewPrinter(command)
+	if err != nil {
+		return err
+	}
+
+	a.InitPlugins(*a.Config().PluginSettings.Directory, *a.Config().PluginSettings.ClientDirectory, nil)
+
+	plugins, appErr := a.GetPlugins()
+	if appErr != nil {
+		return errors.New("Unable to list plugins. Error: " + appErr.Error())
+	}
+
+	records := make([]*pluginRecord, 0, len(plugins.Active)+len(plugins.Inactive))
+	for _, plugin := range plugins.Active {
+		records = append(records, newPluginRecord(plugin, true))
+	}
+	for _, plugin := range plugins.Inactive {
+		records = append(records, newPluginRecord(plugin, false))
+	}
+
+	return printer.PrintRecords(records)
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const oversizedPropsBatchSize = 1000
+
+var PostCmd = &cobra.Command{
+	Use:   "post",
+	Short: "Management of posts",
+}
+
+var PostOversizedPropsCmd = &cobra.Command{
+	Use:   "oversized-props",
+	Short: "List posts with oversized props",
+	Long: `List the existing posts whose serialized props are larger than a given size, which defaults to ServiceSettings.MaxPostPropsSize.
+New posts and edits with props that large are rejected, but posts saved before the limit was in place aren't changed.`,
+	Example: "  post oversized-props --size 4000",
+	RunE:    postOversizedPropsCmdF,
+}
+
+func init() {
+	PostOversizedPropsCmd.Flags().Int("size", 0, "Size in bytes that the props must be larger than. Defaults to ServiceSettings.MaxPostPropsSize")
+
+	PostCmd.AddCommand(
+		PostOversizedPropsCmd,
+	)
+	RootCmd.AddCommand(PostCmd)
+}
+
+func postOversizedPropsCmdF(command *cobra.Command, args []string) error {
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	size, _ := command.Flags().GetInt("size")
+	if size < 0 {
+		return errors.New("Size can't be negative")
+	} else if size == 0 {
+		size = *a.Config().ServiceSettings.MaxPostPropsSize
+	}
+
+	found := 0
+	afterId := ""
+	for {
+		result := <-a.Srv.Store.Post().GetPostsWithPropsLargerThan(size, afterId, oversizedPropsBatchSize)
+		if result.Err != nil {
+			return errors.New("Unable to list posts: " + result.Err.Error())
+		}
+
+		sizes := result.Data.([]*model.PostPropsSize)
+		for _, postSize := range sizes {
+			CommandPrintln(fmt.Sprintf("%s channel_id=%s user_id=%s create_at=%d size=%d", postSize.PostId, postSize.ChannelId, postSize.UserId, postSize.CreateAt, postSize.Size))
+		}
+
+		found += len(sizes)
+		if len(sizes) < oversizedPropsBatchSize {
+			break
+		}
+		afterId = sizes[len(sizes)-1].PostId
+	}
+
+	CommandPrettyPrintln(fmt.Sprintf("Found %d posts with props larger than %d bytes", found, size))
+
+	return nil
+}
@@ -0,0 +1,39 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/api4"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestPostOversizedProps(t *testing.T) {
+	th := api4.Setup().InitBasic()
+	defer th.TearDown()
+
+	// Saved straight to the store as if it had been created before the limit was in place
+	result := <-th.App.Srv.Store.Post().Save(&model.Post{
+		ChannelId: th.BasicChannel.Id,
+		UserId:    th.BasicUser.Id,
+		Message:   "oversized",
+		Props:     model.StringInterface{"attachments": strings.Repeat("a", 7000)},
+	})
+	require.Nil(t, result.Err)
+	oversized := result.Data.(*model.Post)
+
+	output := CheckCommand(t, "post", "oversized-props", "--size", "6000")
+	assert.Contains(t, output, oversized.Id+" channel_id="+th.BasicChannel.Id)
+	assert.NotContains(t, output, th.BasicPost.Id)
+
+	output = CheckCommand(t, "post", "oversized-props", "--size", "7500")
+	assert.NotContains(t, output, oversized.Id)
+
+	require.Error(t, RunCommand(t, "post", "oversized-props", "--size", "-1"))
+}
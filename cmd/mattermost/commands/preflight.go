@@ -0,0 +1,84 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"fmt"
+
+	"github.com/mattermost/mattermost-server/app"
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils"
+	"github.com/spf13/cobra"
+)
+
+var PreflightCmd = &cobra.Command{
+	Use:   "preflight",
+	Short: "Check that the server can be started",
+	Long: `Check the database, file storage, site URL, SMTP server, listen address, and log and plugin directories that the config points to, without starting the server or changing the database. Each check passes, warns, or fails, and the command fails if any of the checks do.
+
+The server runs the same checks when it starts unless ServiceSettings.EnablePreflightChecks is false, and won't start if any fail unless ServiceSettings.AbortStartupOnPreflightFailure is false.`,
+	RunE:         preflightCmdF,
+	SilenceUsage: true,
+}
+
+func init() {
+	RootCmd.AddCommand(PreflightCmd)
+}
+
+func preflightCmdF(command *cobra.Command, args []string) error {
+	if err := utils.TranslationsPreInit(); err != nil {
+		return err
+	}
+	model.AppErrorInit(utils.T)
+
+	configFileLocation, err := command.Flags().GetString("config")
+	if err != nil {
+		return err
+	}
+
+	config, _, _, appErr := utils.LoadConfig(configFileLocation)
+	if appErr != nil {
+		return appErr
+	}
+
+	report := app.RunPreflightChecks(config)
+	for _, check := range report.Checks {
+		CommandPrettyPrintln(check.String())
+	}
+
+	if failures := report.Failures(); len(failures) > 0 {
+		return fmt.Errorf("%v preflight checks failed", len(failures))
+	}
+
+	return nil
+}
+
+// runPreflightChecks logs the results of the preflight checks, and returns an error if any failed and the server
+// shouldn't be started.
+func runPreflightChecks(config *model.Config) error {
+	report := app.RunPreflightChecks(config)
+	for _, check := range report.Checks {
+		switch check.Status {
+		case model.PREFLIGHT_STATUS_FAIL:
+			mlog.Error("Preflight check: " + check.String())
+		case model.PREFLIGHT_STATUS_WARN:
+			mlog.Warn("Preflight check: " + check.String())
+		default:
+			mlog.Info("Preflight check: " + check.String())
+		}
+	}
+
+	failures := report.Failures()
+	if len(failures) == 0 {
+		return nil
+	}
+
+	if !*config.ServiceSettings.AbortStartupOnPreflightFailure {
+		mlog.Warn(fmt.Sprintf("Starting the server even though %v preflight checks failed since ServiceSettings.AbortStartupOnPreflightFailure is false", len(failures)))
+		return nil
+	}
+
+	return fmt.Errorf("The server wasn't started since %v preflight checks failed. The first was: %v", len(failures), failures[0].String())
+}
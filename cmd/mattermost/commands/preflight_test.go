@@ -0,0 +1,58 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestPreflight(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// The file storage can't be written to since it would have to be made under a file
+	file := filepath.Join(dir, "file")
+	require.NoError(t, ioutil.WriteFile(file, []byte("not a directory"), 0600))
+
+	// The port is already in use
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	config := &model.Config{}
+	config.SetDefaults()
+	config.FileSettings.Directory = filepath.Join(file, "data")
+	*config.ServiceSettings.ListenAddress = listener.Addr().String()
+	*config.ServiceSettings.SiteURL = "http://localhost:8065"
+	*config.SqlSettings.DataSource = "mmuser:mostest@tcp(localhost:1)/mattermost_test?charset=utf8mb4,utf8&readTimeout=5s&timeout=5s"
+	*config.PluginSettings.Directory = filepath.Join(dir, "plugins")
+	*config.PluginSettings.ClientDirectory = filepath.Join(dir, "client", "plugins")
+	config.LogSettings.EnableFile = false
+
+	configPath := filepath.Join(dir, "config.json")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(config.ToJson()), 0600))
+
+	executable, err := os.Executable()
+	require.NoError(t, err)
+
+	output, err := exec.Command(executable, execArgs(t, []string{"--config", configPath, "preflight"})...).CombinedOutput()
+	require.Error(t, err)
+	assert.Contains(t, string(output), "FAIL Database:")
+	assert.Contains(t, string(output), "FAIL File storage:")
+	assert.Contains(t, string(output), "FAIL Listen address:")
+	assert.Contains(t, string(output), "PASS Site URL:")
+	assert.Contains(t, string(output), "PASS Plugin directory:")
+	assert.Contains(t, string(output), "3 preflight checks failed")
+}
@@ -0,0 +1,316 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mattermost/mattermost-server/app"
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/spf13/cobra"
+)
+
+var RetentionCmd = &cobra.Command{
+	Use:   "retention",
+	Short: "Management of basic data retention",
+}
+
+var RetentionRunCmd = &cobra.Command{
+	Use:     "run",
+	Short:   "Run basic data retention",
+	Long:    "Permanently delete the messages and files that are older than the retention period of their channel.",
+	Example: "  retention run --dry-run",
+	RunE:    retentionRunCmdF,
+}
+
+var RetentionPolicyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Management of retention policies",
+}
+
+var RetentionPolicyCreateCmd = &cobra.Command{
+	Use:     "create",
+	Short:   "Create a retention policy",
+	Long:    "Create a retention policy. A retention period of 0 days keeps messages or files forever.",
+	Example: "  retention policy create --display_name \"Short lived\" --message_retention_days 30",
+	RunE:    retentionPolicyCreateCmdF,
+}
+
+var RetentionPolicyListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List all retention policies",
+	Example: "  retention policy list",
+	RunE:    retentionPolicyListCmdF,
+}
+
+var RetentionPolicyDeleteCmd = &cobra.Command{
+	Use:     "delete [policies]",
+	Short:   "Delete retention policies",
+	Long:    "Delete retention policies. Their channels and teams fall back to the global retention settings.",
+	Example: "  retention policy delete 4xp9fdt77pncbef59f4k1qe83o",
+	RunE:    retentionPolicyDeleteCmdF,
+}
+
+var RetentionPolicyAddChannelsCmd = &cobra.Command{
+	Use:     "add_channels [policy] [channels]",
+	Short:   "Assign channels to a retention policy",
+	Long:    "Assign channels to a retention policy. Channels can be specified by [team]:[channel]. ie. myteam:mychannel or by channel ID.",
+	Example: "  retention policy add_channels 4xp9fdt77pncbef59f4k1qe83o myteam:mychannel",
+	RunE:    retentionPolicyAddChannelsCmdF,
+}
+
+var RetentionPolicyRemoveChannelsCmd = &cobra.Command{
+	Use:     "remove_channels [policy] [channels]",
+	Short:   "Unassign channels from a retention policy",
+	Long:    "Unassign channels from a retention policy. Channels can be specified by [team]:[channel]. ie. myteam:mychannel or by channel ID.",
+	Example: "  retention policy remove_channels 4xp9fdt77pncbef59f4k1qe83o myteam:mychannel",
+	RunE:    retentionPolicyRemoveChannelsCmdF,
+}
+
+var RetentionPolicyAddTeamsCmd = &cobra.Command{
+	Use:     "add_teams [policy] [teams]",
+	Short:   "Assign teams to a retention policy",
+	Long:    "Assign teams to a retention policy. Channels with a policy of their own keep it.",
+	Example: "  retention policy add_teams 4xp9fdt77pncbef59f4k1qe83o myteam",
+	RunE:    retentionPolicyAddTeamsCmdF,
+}
+
+var RetentionPolicyRemoveTeamsCmd = &cobra.Command{
+	Use:     "remove_teams [policy] [teams]",
+	Short:   "Unassign teams from a retention policy",
+	Example: "  retention policy remove_teams 4xp9fdt77pncbef59f4k1qe83o myteam",
+	RunE:    retentionPolicyRemoveTeamsCmdF,
+}
+
+func init() {
+	RetentionRunCmd.Flags().Bool("dry-run", false, "Report how many messages and files would be deleted without deleting them.")
+
+	RetentionPolicyCreateCmd.Flags().String("display_name", "", "Policy Display Name")
+	RetentionPolicyCreateCmd.Flags().Int("message_retention_days", 0, "Number of days messages are kept for, or 0 to keep them forever.")
+	RetentionPolicyCreateCmd.Flags().Int("file_retention_days", 0, "Number of days files are kept for, or 0 to keep them forever.")
+
+	RetentionPolicyCmd.AddCommand(
+		RetentionPolicyCreateCmd,
+		RetentionPolicyListCmd,
+		RetentionPolicyDeleteCmd,
+		RetentionPolicyAddChannelsCmd,
+		RetentionPolicyRemoveChannelsCmd,
+		RetentionPolicyAddTeamsCmd,
+		RetentionPolicyRemoveTeamsCmd,
+	)
+	RetentionCmd.AddCommand(
+		RetentionRunCmd,
+		RetentionPolicyCmd,
+	)
+	RootCmd.AddCommand(RetentionCmd)
+}
+
+func retentionRunCmdF(command *cobra.Command, args []string) error {
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	dryRun, _ := command.Flags().GetBool("dry-run")
+
+	result, appErr := a.RunBasicRetention(dryRun, nil)
+	if appErr != nil {
+		return appErr
+	}
+
+	if dryRun {
+		CommandPrettyPrintln(fmt.Sprintf("Would delete %d messages and %d files", result.PostsDeleted, result.FilesDeleted))
+	} else {
+		CommandPrettyPrintln(fmt.Sprintf("Deleted %d messages and %d files", result.PostsDeleted, result.FilesDeleted))
+	}
+
+	return nil
+}
+
+func retentionPolicyCreateCmdF(command *cobra.Command, args []string) error {
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	displayName, errdn := command.Flags().GetString("display_name")
+	if errdn != nil || displayName == "" {
+		return errors.New("Display Name is required")
+	}
+	messageRetentionDays, _ := command.Flags().GetInt("message_retention_days")
+	fileRetentionDays, _ := command.Flags().GetInt("file_retention_days")
+
+	policy, appErr := a.CreateRetentionPolicy(&model.RetentionPolicy{
+		DisplayName:          displayName,
+		MessageRetentionDays: messageRetentionDays,
+		FileRetentionDays:    fileRetentionDays,
+	})
+	if appErr != nil {
+		return appErr
+	}
+
+	CommandPrettyPrintln("Created retention policy '" + policy.DisplayName + "' with id " + policy.Id)
+
+	return nil
+}
+
+func retentionPolicyListCmdF(command *cobra.Command, args []string) error {
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	policies, appErr := a.GetRetentionPolicies()
+	if appErr != nil {
+		return appErr
+	}
+
+	for _, policy := range policies {
+		CommandPrettyPrintln(fmt.Sprintf("%s: %s (messages: %d days, files: %d days, channels: %d, teams: %d)", policy.Id, policy.DisplayName, policy.MessageRetentionDays, policy.FileRetentionDays, len(policy.ChannelIds), len(policy.TeamIds)))
+	}
+
+	return nil
+}
+
+func retentionPolicyDeleteCmdF(command *cobra.Command, args []string) error {
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	if len(args) < 1 {
+		return errors.New("Enter at least one policy.")
+	}
+
+	for _, policyId := range args {
+		if appErr := a.DeleteRetentionPolicy(policyId); appErr != nil {
+			CommandPrintErrorln("Unable to delete policy '" + policyId + "' error: " + appErr.Error())
+		} else {
+			CommandPrettyPrintln("Deleted retention policy '" + policyId + "'")
+		}
+	}
+
+	return nil
+}
+
+func getRetentionChannelIds(a *app.App, channelArgs []string) ([]string, error) {
+	channels := getChannelsFromChannelArgs(a, channelArgs)
+	channelIds := make([]string, 0, len(channels))
+	for i, channel := range channels {
+		if channel == nil {
+			return nil, errors.New("Unable to find channel '" + channelArgs[i] + "'")
+		}
+		channelIds = append(channelIds, channel.Id)
+	}
+	return channelIds, nil
+}
+
+func getRetentionTeamIds(a *app.App, teamArgs []string) ([]string, error) {
+	teams := getTeamsFromTeamArgs(a, teamArgs)
+	teamIds := make([]string, 0, len(teams))
+	for i, team := range teams {
+		if team == nil {
+			return nil, errors.New("Unable to find team '" + teamArgs[i] + "'")
+		}
+		teamIds = append(teamIds, team.Id)
+	}
+	return teamIds, nil
+}
+
+func retentionPolicyAddChannelsCmdF(command *cobra.Command, args []string) error {
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	if len(args) < 2 {
+		return errors.New("Not enough arguments.")
+	}
+
+	channelIds, err := getRetentionChannelIds(a, args[1:])
+	if err != nil {
+		return err
+	}
+
+	if appErr := a.AddChannelsToRetentionPolicy(args[0], channelIds); appErr != nil {
+		return appErr
+	}
+
+	return nil
+}
+
+func retentionPolicyRemoveChannelsCmdF(command *cobra.Command, args []string) error {
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	if len(args) < 2 {
+		return errors.New("Not enough arguments.")
+	}
+
+	channelIds, err := getRetentionChannelIds(a, args[1:])
+	if err != nil {
+		return err
+	}
+
+	if appErr := a.RemoveChannelsFromRetentionPolicy(args[0], channelIds); appErr != nil {
+		return appErr
+	}
+
+	return nil
+}
+
+func retentionPolicyAddTeamsCmdF(command *cobra.Command, args []string) error {
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	if len(args) < 2 {
+		return errors.New("Not enough arguments.")
+	}
+
+	teamIds, err := getRetentionTeamIds(a, args[1:])
+	if err != nil {
+		return err
+	}
+
+	if appErr := a.AddTeamsToRetentionPolicy(args[0], teamIds); appErr != nil {
+		return appErr
+	}
+
+	return nil
+}
+
+func retentionPolicyRemoveTeamsCmdF(command *cobra.Command, args []string) error {
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	if len(args) < 2 {
+		return errors.New("Not enough arguments.")
+	}
+
+	teamIds, err := getRetentionTeamIds(a, args[1:])
+	if err != nil {
+		return err
+	}
+
+	if appErr := a.RemoveTeamsFromRetentionPolicy(args[0], teamIds); appErr != nil {
+		return appErr
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/api4"
+)
+
+func TestRetentionPolicyCommands(t *testing.T) {
+	th := api4.Setup().InitBasic()
+	defer th.TearDown()
+
+	output := CheckCommand(t, "retention", "policy", "create", "--display_name", "Short lived", "--message_retention_days", "30")
+	assert.Contains(t, output, "Created retention policy 'Short lived'")
+
+	policies, err := th.App.GetRetentionPolicies()
+	require.Nil(t, err)
+
+	policyId := ""
+	for _, policy := range policies {
+		if policy.DisplayName == "Short lived" && strings.Contains(output, policy.Id) {
+			policyId = policy.Id
+		}
+	}
+	require.NotEmpty(t, policyId)
+	defer th.App.DeleteRetentionPolicy(policyId)
+
+	CheckCommand(t, "retention", "policy", "add_channels", policyId, th.BasicTeam.Name+":"+th.BasicChannel.Name)
+	CheckCommand(t, "retention", "policy", "add_teams", policyId, th.BasicTeam.Name)
+
+	policy, err := th.App.GetRetentionPolicy(policyId)
+	require.Nil(t, err)
+	assert.Equal(t, []string{th.BasicChannel.Id}, policy.ChannelIds)
+	assert.Equal(t, []string{th.BasicTeam.Id}, policy.TeamIds)
+
+	require.Error(t, RunCommand(t, "retention", "policy", "add_channels", policyId, "nonexistent:channel"))
+
+	CheckCommand(t, "retention", "policy", "remove_channels", policyId, th.BasicChannel.Id)
+	CheckCommand(t, "retention", "policy", "remove_teams", policyId, th.BasicTeam.Name)
+
+	policy, err = th.App.GetRetentionPolicy(policyId)
+	require.Nil(t, err)
+	assert.Len(t, policy.ChannelIds, 0)
+	assert.Len(t, policy.TeamIds, 0)
+
+	assert.Contains(t, CheckCommand(t, "retention", "run", "--dry-run"), "Would delete")
+
+	CheckCommand(t, "retention", "policy", "delete", policyId)
+	_, err = th.App.GetRetentionPolicy(policyId)
+	assert.NotNil(t, err)
+}
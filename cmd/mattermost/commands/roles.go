@@ -7,6 +7,8 @@ import (
 	"errors"
 
 	"github.com/spf13/cobra"
+
+	"github.com/mattermost/mattermost-server/model"
 )
 
 var RolesCmd = &cobra.Command{
@@ -22,6 +24,22 @@ var MakeSystemAdminCmd = &cobra.Command{
 	RunE:    makeSystemAdminCmdF,
 }
 
+var MakeReadOnlyAdminCmd = &cobra.Command{
+	Use:     "read_only_admin [users]",
+	Short:   "Set a user as read only system admin",
+	Long:    "Make some users read only system admins, who can view the System Console without changing anything in it.",
+	Example: "  roles read_only_admin user1",
+	RunE:    makeReadOnlyAdminCmdF,
+}
+
+var MakeUserManagerCmd = &cobra.Command{
+	Use:     "user_manager [users]",
+	Short:   "Set a user as user manager",
+	Long:    "Make some users user managers, who can manage other users and their team memberships without access to the rest of the System Console.",
+	Example: "  roles user_manager user1",
+	RunE:    makeUserManagerCmdF,
+}
+
 var MakeMemberCmd = &cobra.Command{
 	Use:     "member [users]",
 	Short:   "Remove system admin privileges",
@@ -33,37 +51,30 @@ var MakeMemberCmd = &cobra.Command{
 func init() {
 	RolesCmd.AddCommand(
 		MakeSystemAdminCmd,
+		MakeReadOnlyAdminCmd,
+		MakeUserManagerCmd,
 		MakeMemberCmd,
 	)
 	RootCmd.AddCommand(RolesCmd)
 }
 
 func makeSystemAdminCmdF(command *cobra.Command, args []string) error {
-	a, err := InitDBCommandContextCobra(command)
-	if err != nil {
-		return err
-	}
-	defer a.Shutdown()
-
-	if len(args) < 1 {
-		return errors.New("Enter at least one user.")
-	}
-
-	users := getUsersFromUserArgs(a, args)
-	for i, user := range users {
-		if user == nil {
-			return errors.New("Unable to find user '" + args[i] + "'")
-		}
+	return setUserRolesCmdF(command, args, model.SYSTEM_ADMIN_ROLE_ID+" "+model.SYSTEM_USER_ROLE_ID)
+}
 
-		if _, err := a.UpdateUserRoles(user.Id, "system_admin system_user", true); err != nil {
-			return err
-		}
-	}
+func makeReadOnlyAdminCmdF(command *cobra.Command, args []string) error {
+	return setUserRolesCmdF(command, args, model.SYSTEM_READ_ONLY_ADMIN_ROLE_ID+" "+model.SYSTEM_USER_ROLE_ID)
+}
 
-	return nil
+func makeUserManagerCmdF(command *cobra.Command, args []string) error {
+	return setUserRolesCmdF(command, args, model.SYSTEM_USER_MANAGER_ROLE_ID+" "+model.SYSTEM_USER_ROLE_ID)
 }
 
 func makeMemberCmdF(command *cobra.Command, args []string) error {
+	return setUserRolesCmdF(command, args, model.SYSTEM_USER_ROLE_ID)
+}
+
+func setUserRolesCmdF(command *cobra.Command, args []string, roles string) error {
 	a, err := InitDBCommandContextCobra(command)
 	if err != nil {
 		return err
@@ -80,7 +91,7 @@ func makeMemberCmdF(command *cobra.Command, args []string) error {
 			return errors.New("Unable to find user '" + args[i] + "'")
 		}
 
-		if _, err := a.UpdateUserRoles(user.Id, "system_user", true); err != nil {
+		if _, err := a.UpdateUserRoles(user.Id, roles, true); err != nil {
 			return err
 		}
 	}
@@ -6,6 +6,9 @@ package commands
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"github.com/mattermost/mattermost-server/api4"
 	"github.com/mattermost/mattermost-server/model"
 )
@@ -25,3 +28,25 @@ func TestAssignRole(t *testing.T) {
 		}
 	}
 }
+
+func TestAssignLimitedAdminRoles(t *testing.T) {
+	th := api4.Setup().InitBasic()
+	defer th.TearDown()
+
+	for command, role := range map[string]string{
+		"read_only_admin": model.SYSTEM_READ_ONLY_ADMIN_ROLE_ID,
+		"user_manager":    model.SYSTEM_USER_MANAGER_ROLE_ID,
+	} {
+		CheckCommand(t, "roles", command, th.BasicUser.Email)
+
+		result := <-th.App.Srv.Store.User().GetByEmail(th.BasicUser.Email)
+		require.Nil(t, result.Err)
+		assert.Equal(t, role+" system_user", result.Data.(*model.User).Roles)
+	}
+
+	CheckCommand(t, "roles", "member", th.BasicUser.Email)
+
+	result := <-th.App.Srv.Store.User().GetByEmail(th.BasicUser.Email)
+	require.Nil(t, result.Err)
+	assert.Equal(t, "system_user", result.Data.(*model.User).Roles)
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/mattermost/mattermost-server/app"
+	"github.com/spf13/cobra"
+)
+
+const OLD_AT_REST_ENCRYPT_KEY_ENV = "MM_OLD_AT_REST_ENCRYPT_KEY"
+
+var SecurityCmd = &cobra.Command{
+	Use:   "security",
+	Short: "Management of security secrets",
+}
+
+var SecurityRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Replace security secrets with new random ones",
+	Long: `Replace the chosen security secrets in the config with new random ones. The config is only saved once every secret has been replaced.
+Rotating the at rest encryption key requires the current key, given by --old-at-rest-key or the ` + OLD_AT_REST_ENCRYPT_KEY_ENV + ` environment variable.
+Rotating the public link salt invalidates every channel feed token and the legacy public file links that weren't saved.`,
+	Example: "  security rotate --public-link-salt",
+	RunE:    securityRotateCmdF,
+}
+
+func init() {
+	SecurityRotateCmd.Flags().Bool("at-rest-key", false, "Rotate the at rest encryption key.")
+	SecurityRotateCmd.Flags().String("old-at-rest-key", "", "The current at rest encryption key. Defaults to the "+OLD_AT_REST_ENCRYPT_KEY_ENV+" environment variable.")
+	SecurityRotateCmd.Flags().Bool("public-link-salt", false, "Rotate the public link salt.")
+
+	SecurityCmd.AddCommand(
+		SecurityRotateCmd,
+	)
+	RootCmd.AddCommand(SecurityCmd)
+}
+
+func securityRotateCmdF(command *cobra.Command, args []string) error {
+	rotateAtRestKey, _ := command.Flags().GetBool("at-rest-key")
+	rotatePublicLinkSalt, _ := command.Flags().GetBool("public-link-salt")
+	if !rotateAtRestKey && !rotatePublicLinkSalt {
+		return errors.New("Choose at least one of --at-rest-key or --public-link-salt.")
+	}
+
+	rotation := &app.SecretRotation{
+		PublicLinkSalt: rotatePublicLinkSalt,
+	}
+
+	if rotateAtRestKey {
+		oldKey, _ := command.Flags().GetString("old-at-rest-key")
+		if oldKey == "" {
+			oldKey = os.Getenv(OLD_AT_REST_ENCRYPT_KEY_ENV)
+		}
+		if oldKey == "" {
+			return errors.New("The current at rest encryption key is required with --old-at-rest-key or " + OLD_AT_REST_ENCRYPT_KEY_ENV + ".")
+		}
+		rotation.OldAtRestEncryptKey = oldKey
+	}
+
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	result, appErr := a.RotateSecrets(rotation)
+	if appErr != nil {
+		return appErr
+	}
+
+	if rotateAtRestKey {
+		CommandPrettyPrintln(fmt.Sprintf("Rotated the at rest encryption key, encrypting %d saved versions of the config with the new one", result.ConfigVersionsReencrypted))
+	}
+	if rotatePublicLinkSalt {
+		CommandPrettyPrintln(fmt.Sprintf("Rotated the public link salt, invalidating %d channel feed tokens and any legacy public file links", result.FeedTokensInvalidated))
+	}
+
+	return nil
+}
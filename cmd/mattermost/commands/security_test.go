@@ -0,0 +1,67 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/api4"
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+	"github.com/mattermost/mattermost-server/utils"
+)
+
+func TestSecurityRotate(t *testing.T) {
+	th := api4.Setup().InitBasic()
+	defer th.TearDown()
+
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Rotate the secrets in a copy of the config so that the one used by the other tests isn't changed
+	path := filepath.Join(dir, "config.json")
+	config := th.App.Config().Clone()
+	require.NoError(t, ioutil.WriteFile(path, []byte(config.ToJson()), 0600))
+
+	readConfig := func() *model.Config {
+		data, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		return model.ConfigFromJson(bytes.NewReader(data))
+	}
+
+	require.Error(t, RunCommand(t, "--config", path, "security", "rotate"), "a secret to rotate should be required")
+	require.Error(t, RunCommand(t, "--config", path, "security", "rotate", "--at-rest-key"), "the old key should be required")
+	require.Error(t, RunCommand(t, "--config", path, "security", "rotate", "--at-rest-key", "--old-at-rest-key", model.NewRandomString(32)))
+	assert.Equal(t, config.SqlSettings.AtRestEncryptKey, readConfig().SqlSettings.AtRestEncryptKey)
+
+	// Other tests save versions of the config with their own keys, so those are made readable with this one first
+	empty, err := utils.EncryptAtRest(config.SqlSettings.AtRestEncryptKey, []byte("{}"))
+	require.NoError(t, err)
+	unreadable := make(map[string]string)
+	for _, history := range store.Must(th.App.Srv.Store.ConfigurationHistory().GetPage(0, 10000)).([]*model.ConfigurationHistory) {
+		if _, err := utils.DecryptAtRest(config.SqlSettings.AtRestEncryptKey, history.Secrets); err != nil {
+			unreadable[history.Id] = empty
+		}
+	}
+	store.Must(th.App.Srv.Store.ConfigurationHistory().UpdateSecrets(unreadable))
+
+	output := CheckCommand(t, "--config", path, "security", "rotate", "--at-rest-key", "--old-at-rest-key", config.SqlSettings.AtRestEncryptKey, "--public-link-salt")
+	assert.Contains(t, output, "Rotated the at rest encryption key")
+	assert.Contains(t, output, "Rotated the public link salt")
+
+	rotated := readConfig()
+	assert.NotEqual(t, config.SqlSettings.AtRestEncryptKey, rotated.SqlSettings.AtRestEncryptKey)
+	assert.NotEqual(t, *config.FileSettings.PublicLinkSalt, *rotated.FileSettings.PublicLinkSalt)
+
+	// The data is still readable with only the new key configured
+	assert.Contains(t, CheckCommand(t, "--config", path, "team", "list"), th.BasicTeam.Name)
+}
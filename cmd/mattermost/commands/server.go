@@ -24,10 +24,6 @@ import (
 	"github.com/spf13/cobra"
 )
 
-const (
-	SESSIONS_CLEANUP_BATCH_SIZE = 1000
-)
-
 var MaxNotificationsPerChannelDefault int64 = 1000000
 
 var serverCmd = &cobra.Command{
@@ -56,6 +52,15 @@ func serverCmdF(command *cobra.Command, args []string) error {
 }
 
 func runServer(configFileLocation string, disableConfigWatch bool, usedPlatform bool, interruptChan chan os.Signal) error {
+	// The checks are made before the server is set up since that exits on some of the problems that they look for. A
+	// config that can't be loaded is left for the server to report.
+	if config, _, _, appErr := utils.LoadConfig(configFileLocation); appErr == nil && *config.ServiceSettings.EnablePreflightChecks {
+		if err := runPreflightChecks(config); err != nil {
+			mlog.Critical(err.Error())
+			return err
+		}
+	}
+
 	options := []app.Option{app.ConfigFile(configFileLocation)}
 	if disableConfigWatch {
 		options = append(options, app.DisableConfigWatch)
@@ -79,6 +84,14 @@ func runServer(configFileLocation string, disableConfigWatch bool, usedPlatform
 	mlog.Info(fmt.Sprintf("Current working directory is %v", pwd))
 	mlog.Info(fmt.Sprintf("Loaded config file from %v", utils.FindConfigFile(configFileLocation)))
 
+	if missing, err := utils.MissingTranslations(); err != nil {
+		mlog.Error("Failed to check translations: " + err.Error())
+	} else {
+		for locale, ids := range missing {
+			mlog.Info(fmt.Sprintf("Translations for %v are missing %v messages, which are shown in English instead", locale, len(ids)))
+		}
+	}
+
 	backend, appErr := a.FileBackend()
 	if appErr == nil {
 		appErr = backend.TestConnection()
@@ -91,7 +104,15 @@ func runServer(configFileLocation string, disableConfigWatch bool, usedPlatform
 		a.LoadLicense()
 	}
 
-	a.DoAdvancedPermissionsMigration()
+	if *a.Config().ServiceSettings.SkipMigrations {
+		if err := checkAdvancedMigrations(a); err != nil {
+			mlog.Critical(err.Error())
+			return err
+		}
+	} else if err := doAdvancedPermissionsMigration(a); err != nil {
+		mlog.Critical(err.Error())
+		return err
+	}
 
 	a.InitPlugins(*a.Config().PluginSettings.Directory, *a.Config().PluginSettings.ClientDirectory, nil)
 	a.AddConfigListener(func(prevCfg, cfg *model.Config) {
@@ -109,9 +130,16 @@ func runServer(configFileLocation string, disableConfigWatch bool, usedPlatform
 	}
 
 	api := api4.Init(a, a.Srv.Router)
+	api4.InitLocal(a, a.Srv.LocalRouter)
 	wsapi.Init(a, a.Srv.WebSocketRouter)
 	web.NewWeb(a, a.Srv.Router)
 
+	// The disabled API endpoints can only be checked against the routes once they've been added
+	if err := a.ValidateDisabledAPIEndpoints(a.Config()); err != nil {
+		mlog.Critical(err.Error())
+		return err
+	}
+
 	license := a.License()
 
 	if license == nil && len(a.Config().SqlSettings.DataSourceReplicas) > 1 {
@@ -164,12 +192,21 @@ func runServer(configFileLocation string, disableConfigWatch bool, usedPlatform
 	a.Go(func() {
 		runSessionCleanupJob(a)
 	})
+	a.Go(func() {
+		runTableTrimJob(a)
+	})
 	a.Go(func() {
 		runTokenCleanupJob(a)
 	})
 	a.Go(func() {
 		runCommandWebhookCleanupJob(a)
 	})
+	a.Go(func() {
+		runPostIdempotencyKeyCleanupJob(a)
+	})
+	a.Go(func() {
+		runEmojiStatsJob(a)
+	})
 
 	if complianceI := a.Compliance; complianceI != nil {
 		complianceI.StartComplianceDailyJob()
@@ -248,10 +285,31 @@ func runCommandWebhookCleanupJob(a *app.App) {
 	}, time.Hour*1)
 }
 
+func runPostIdempotencyKeyCleanupJob(a *app.App) {
+	a.CleanupPostIdempotencyKeys()
+	model.CreateRecurringTask("Post Idempotency Key Cleanup", func() {
+		a.CleanupPostIdempotencyKeys()
+	}, time.Hour*1)
+}
+
 func runSessionCleanupJob(a *app.App) {
 	doSessionCleanup(a)
 	model.CreateRecurringTask("Session Cleanup", func() {
 		doSessionCleanup(a)
+	}, time.Duration(*a.Config().ServiceSettings.SessionCleanupIntervalMinutes)*time.Minute)
+}
+
+func runTableTrimJob(a *app.App) {
+	a.TrimTablesByRetention()
+	model.CreateRecurringTask("Table Trim", func() {
+		a.TrimTablesByRetention()
+	}, time.Duration(*a.Config().ServiceSettings.TableTrimIntervalMinutes)*time.Minute)
+}
+
+func runEmojiStatsJob(a *app.App) {
+	doEmojiStats(a)
+	model.CreateRecurringTask("Emoji Stats", func() {
+		doEmojiStats(a)
 	}, time.Hour*24)
 }
 
@@ -308,6 +366,14 @@ func doCommandWebhookCleanup(a *app.App) {
 	a.Srv.Store.CommandWebhook().Cleanup()
 }
 
+func doEmojiStats(a *app.App) {
+	if err := a.RollUpEmojiStats(); err != nil {
+		mlog.Error(fmt.Sprintf("Unable to roll up emoji stats, err=%v", err))
+	}
+}
+
 func doSessionCleanup(a *app.App) {
-	a.Srv.Store.Session().Cleanup(model.GetMillis(), SESSIONS_CLEANUP_BATCH_SIZE)
+	if _, err := a.CleanupSessions(); err != nil {
+		mlog.Error(fmt.Sprintf("Unable to clean up sessions, err=%v", err))
+	}
 }
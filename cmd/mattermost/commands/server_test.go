@@ -7,12 +7,15 @@ import (
 	"io/ioutil"
 	"net"
 	"os"
+	"path/filepath"
 	"syscall"
 	"testing"
 
 	"github.com/mattermost/mattermost-server/jobs"
 	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store/sqlstore"
 	"github.com/mattermost/mattermost-server/utils"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -142,3 +145,41 @@ func TestRunServerNoSystemd(t *testing.T) {
 	err := runServer(th.configPath, th.disableConfigWatch, false, th.interruptChan)
 	require.NoError(t, err)
 }
+
+func TestRunServerSkipMigrations(t *testing.T) {
+	th := SetupServerTest()
+	defer th.TearDownServerTest()
+
+	CheckCommand(t, "db", "migrate")
+
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	config, _, _, appErr := utils.LoadConfig(th.configPath)
+	require.Nil(t, appErr)
+	*config.ServiceSettings.SkipMigrations = true
+
+	configPath := filepath.Join(dir, "config.json")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(config.ToJson()), 0600))
+
+	err = runServer(configPath, th.disableConfigWatch, false, th.interruptChan)
+	require.NoError(t, err)
+
+	t.Run("schema behind", func(t *testing.T) {
+		supplier := sqlstore.NewSqlSupplierForSchemaCheck(config.SqlSettings)
+		defer supplier.Close()
+
+		_, err := supplier.GetMaster().Exec("UPDATE Systems SET Value = '4.10.0' WHERE Name = 'Version'")
+		require.NoError(t, err)
+		defer supplier.GetMaster().Exec("UPDATE Systems SET Value = :Version WHERE Name = 'Version'", map[string]interface{}{"Version": model.CurrentVersion})
+
+		err = runServer(configPath, th.disableConfigWatch, false, th.interruptChan)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "db migrate")
+
+		version, err := supplier.GetMaster().SelectStr("SELECT Value FROM Systems WHERE Name = 'Version'")
+		require.NoError(t, err)
+		assert.Equal(t, "4.10.0", version, "the database shouldn't have been migrated")
+	})
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/spf13/cobra"
+)
+
+var SupportPacketCmd = &cobra.Command{
+	Use:   "support-packet",
+	Short: "Support packets for diagnosing problems",
+}
+
+var SupportPacketGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate a support packet",
+	Long: `Generate a zip file with what's needed to diagnose a problem with the server: its config with every secret removed,
+the end of its log, its database schema and migrations, its plugins, the jobs that failed in the last week, the host
+and the cluster. Plugins are started by the command to report on them, so they're as healthy as they are for it.`,
+	Example: "  support-packet generate -o packet.zip --log-megabytes 20",
+	RunE:    supportPacketGenerateCmdF,
+}
+
+func init() {
+	SupportPacketGenerateCmd.Flags().StringP("output", "o", "", "Required. The zip file to write the support packet to.")
+	SupportPacketGenerateCmd.Flags().Int("log-megabytes", model.SUPPORT_PACKET_DEFAULT_LOG_MEGABYTES, "How many megabytes from the end of the log to include.")
+
+	SupportPacketCmd.AddCommand(
+		SupportPacketGenerateCmd,
+	)
+	RootCmd.AddCommand(SupportPacketCmd)
+}
+
+func supportPacketGenerateCmdF(command *cobra.Command, args []string) error {
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	output, _ := command.Flags().GetString("output")
+	if output == "" {
+		return errors.New("Output file is required")
+	}
+
+	logMegabytes, _ := command.Flags().GetInt("log-megabytes")
+	if logMegabytes < 0 || logMegabytes > model.SUPPORT_PACKET_MAX_LOG_MEGABYTES {
+		return fmt.Errorf("Log megabytes must be between 0 and %d", model.SUPPORT_PACKET_MAX_LOG_MEGABYTES)
+	}
+
+	a.InitPlugins(*a.Config().PluginSettings.Directory, *a.Config().PluginSettings.ClientDirectory, nil)
+
+	file, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return errors.New("Unable to create the output file: " + err.Error())
+	}
+	defer file.Close()
+
+	if appErr := a.GenerateSupportPacket(file, int64(logMegabytes)*1024*1024); appErr != nil {
+		file.Close()
+		os.Remove(output)
+		return appErr
+	}
+
+	CommandPrettyPrintln("Generated the support packet " + output)
+
+	return nil
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/api4"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestSupportPacketGenerate(t *testing.T) {
+	th := api4.Setup().InitBasic()
+	defer th.TearDown()
+
+	dir, err := ioutil.TempDir("", "support-packet")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "packet.zip")
+
+	require.Error(t, RunCommand(t, "support-packet", "generate"))
+	require.Error(t, RunCommand(t, "support-packet", "generate", "-o", output, "--log-megabytes", "1000"))
+
+	CheckCommand(t, "support-packet", "generate", "-o", output, "--log-megabytes", "1")
+
+	reader, err := zip.OpenReader(output)
+	require.Nil(t, err)
+	defer reader.Close()
+
+	var names []string
+	for _, file := range reader.File {
+		names = append(names, file.Name)
+	}
+	assert.Contains(t, names, model.SUPPORT_PACKET_MANIFEST_FILE)
+	assert.Contains(t, names, model.SUPPORT_PACKET_CONFIG_FILE)
+
+	require.Error(t, RunCommand(t, "support-packet", "generate", "-o", output), "shouldn't overwrite an existing file")
+}
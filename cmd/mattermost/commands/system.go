@@ -0,0 +1,202 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/spf13/cobra"
+)
+
+// SYSTEM_DRAIN_POLL_INTERVAL is how often system drain --wait checks how far along draining the server is.
+const SYSTEM_DRAIN_POLL_INTERVAL = time.Second
+
+var SystemCmd = &cobra.Command{
+	Use:   "system",
+	Short: "System management",
+}
+
+var SystemBannerCmd = &cobra.Command{
+	Use:   "banner",
+	Short: "Management of the system-wide announcement banner",
+}
+
+var SystemBannerSetCmd = &cobra.Command{
+	Use:     "set",
+	Short:   "Show an announcement banner to all users",
+	Long:    "Show an announcement banner to all users. Running servers pick up the change within a minute.",
+	Example: "  system banner set --text \"Maintenance tonight at 22:00 UTC\" --color \"#f2a93b\" --dismissable --expires_in 12h",
+	RunE:    systemBannerSetCmdF,
+}
+
+var SystemBannerClearCmd = &cobra.Command{
+	Use:     "clear",
+	Short:   "Remove the announcement banner",
+	Long:    "Remove the announcement banner. Running servers pick up the change within a minute.",
+	Example: "  system banner clear",
+	RunE:    systemBannerClearCmdF,
+}
+
+var SystemMaintenanceCmd = &cobra.Command{
+	Use:     "maintenance [on|off]",
+	Short:   "Turn maintenance mode on or off",
+	Long:    "Turn maintenance mode on or off. While it's on, only system admins can use the API and everyone else is disconnected, or with --read-only, everyone can keep reading but nothing can be changed. Running servers pick up the change within 10 seconds.",
+	Example: "  system maintenance on --message \"Upgrading to the latest version\"\n  system maintenance on --read-only\n  system maintenance off",
+	Args:    cobra.ExactArgs(1),
+	RunE:    systemMaintenanceCmdF,
+}
+
+var SystemDrainCmd = &cobra.Command{
+	Use:         "drain",
+	Short:       "Drain the running server before stopping it",
+	Long:        "Drain the server running with this config so that it can be stopped without its users noticing. It refuses new websocket connections so that the load balancer sends them elsewhere, tells the users connected to it to reconnect a few at a time over ServiceSettings.WebsocketDrainWindowSeconds and stops running jobs once the ones it's running have finished. The server must have ServiceSettings.EnableLocalMode set, and keeps draining until it's restarted.",
+	Example:     "  system drain --wait",
+	Annotations: map[string]string{LOCAL_MODE_ANNOTATION: "true"},
+	Args:        cobra.NoArgs,
+	RunE:        systemDrainCmdF,
+}
+
+func init() {
+	SystemBannerSetCmd.Flags().String("text", "", "Banner text")
+	SystemBannerSetCmd.Flags().String("color", "", "Banner background color, ie. #f2a93b")
+	SystemBannerSetCmd.Flags().String("text_color", "", "Banner text color, ie. #333333")
+	SystemBannerSetCmd.Flags().Bool("dismissable", false, "Allow users to dismiss the banner.")
+	SystemBannerSetCmd.Flags().Duration("expires_in", 0, "Remove the banner after this long, ie. 2h30m. By default the banner is shown until it is cleared.")
+
+	SystemMaintenanceCmd.Flags().String("message", "", "Message shown to users while maintenance mode is on")
+	SystemMaintenanceCmd.Flags().Bool("read-only", false, "Keep serving reads while the database is read-only instead of disconnecting everyone")
+
+	SystemDrainCmd.Flags().Bool("wait", false, "Wait until the server has finished draining")
+
+	SystemBannerCmd.AddCommand(
+		SystemBannerSetCmd,
+		SystemBannerClearCmd,
+	)
+	SystemCmd.AddCommand(
+		SystemBannerCmd,
+		SystemMaintenanceCmd,
+		SystemDrainCmd,
+	)
+	RootCmd.AddCommand(SystemCmd)
+}
+
+func systemBannerSetCmdF(command *cobra.Command, args []string) error {
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	text, errt := command.Flags().GetString("text")
+	if errt != nil || text == "" {
+		return errors.New("Text is required")
+	}
+	color, _ := command.Flags().GetString("color")
+	textColor, _ := command.Flags().GetString("text_color")
+	dismissable, _ := command.Flags().GetBool("dismissable")
+	expiresIn, _ := command.Flags().GetDuration("expires_in")
+
+	announcement := &model.Announcement{
+		Text:        text,
+		Color:       color,
+		TextColor:   textColor,
+		Dismissable: dismissable,
+	}
+	if expiresIn > 0 {
+		announcement.ExpiresAt = model.GetMillis() + int64(expiresIn/time.Millisecond)
+	}
+
+	if appErr := a.SetAnnouncement(announcement); appErr != nil {
+		return appErr
+	}
+
+	CommandPrettyPrintln("Announcement banner set")
+
+	return nil
+}
+
+func systemBannerClearCmdF(command *cobra.Command, args []string) error {
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	if appErr := a.ClearAnnouncement(); appErr != nil {
+		return appErr
+	}
+
+	CommandPrettyPrintln("Announcement banner cleared")
+
+	return nil
+}
+
+func systemMaintenanceCmdF(command *cobra.Command, args []string) error {
+	var enabled bool
+	switch args[0] {
+	case "on":
+		enabled = true
+	case "off":
+		enabled = false
+	default:
+		return errors.New("Maintenance mode must be turned on or off")
+	}
+
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	message, _ := command.Flags().GetString("message")
+	readOnly, _ := command.Flags().GetBool("read-only")
+
+	if _, appErr := a.SetMaintenanceMode(enabled, readOnly, message); appErr != nil {
+		return appErr
+	}
+
+	if enabled {
+		CommandPrettyPrintln("Maintenance mode turned on")
+	} else {
+		CommandPrettyPrintln("Maintenance mode turned off")
+	}
+
+	return nil
+}
+
+func systemDrainCmdF(command *cobra.Command, args []string) error {
+	// Only the running server can drain itself, so the command always goes through its local mode socket
+	client, err := InitLocalClient(command)
+	if err != nil {
+		return err
+	}
+
+	status, resp := client.StartDrain()
+	if resp.Error != nil {
+		return resp.Error
+	}
+
+	CommandPrettyPrintln(fmt.Sprintf("Draining the server, telling %v users to reconnect over %v seconds", status.Users, status.WindowSeconds))
+
+	if wait, _ := command.Flags().GetBool("wait"); !wait {
+		return nil
+	}
+
+	for !status.Done {
+		time.Sleep(SYSTEM_DRAIN_POLL_INTERVAL)
+
+		if status, resp = client.GetDrainStatus(); resp.Error != nil {
+			return resp.Error
+		}
+
+		CommandPrettyPrintln(fmt.Sprintf("%v of %v users told to reconnect, %v websocket connections left, jobs finished: %v", status.UsersNotified, status.Users, status.WebSocketConnections, status.JobsFinished))
+	}
+
+	CommandPrettyPrintln("The server has finished draining")
+
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/api4"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestSystemBannerCommands(t *testing.T) {
+	th := api4.Setup().InitBasic()
+	defer th.TearDown()
+
+	defer th.App.ClearAnnouncement()
+
+	require.Error(t, RunCommand(t, "system", "banner", "set"))
+	require.Error(t, RunCommand(t, "system", "banner", "set", "--text", "Maintenance", "--color", "orange"))
+
+	output := CheckCommand(t, "system", "banner", "set", "--text", "Maintenance tonight", "--dismissable", "--expires_in", "1h")
+	assert.Contains(t, output, "Announcement banner set")
+
+	result := <-th.App.Srv.Store.System().Get()
+	require.Nil(t, result.Err)
+	announcement := model.AnnouncementFromJson(strings.NewReader(result.Data.(model.StringMap)[model.SYSTEM_ANNOUNCEMENT]))
+	require.NotNil(t, announcement)
+	assert.Equal(t, "Maintenance tonight", announcement.Text)
+	assert.True(t, announcement.Dismissable)
+	assert.True(t, announcement.ExpiresAt > model.GetMillis())
+
+	output = CheckCommand(t, "system", "banner", "clear")
+	assert.Contains(t, output, "Announcement banner cleared")
+
+	result = <-th.App.Srv.Store.System().Get()
+	require.Nil(t, result.Err)
+	_, ok := result.Data.(model.StringMap)[model.SYSTEM_ANNOUNCEMENT]
+	assert.False(t, ok)
+}
+
+func TestSystemMaintenanceCommands(t *testing.T) {
+	th := api4.Setup().InitBasic()
+	defer th.TearDown()
+
+	defer th.App.SetMaintenanceMode(false, false, "")
+
+	require.Error(t, RunCommand(t, "system", "maintenance"))
+	require.Error(t, RunCommand(t, "system", "maintenance", "maybe"))
+
+	output := CheckCommand(t, "system", "maintenance", "on", "--message", "Back in an hour")
+	assert.Contains(t, output, "Maintenance mode turned on")
+
+	result := <-th.App.Srv.Store.System().Get()
+	require.Nil(t, result.Err)
+	mode := model.MaintenanceModeFromJson(strings.NewReader(result.Data.(model.StringMap)[model.SYSTEM_MAINTENANCE_MODE]))
+	require.NotNil(t, mode)
+	assert.True(t, mode.Enabled)
+	assert.Equal(t, "Back in an hour", mode.Message)
+	assert.True(t, mode.StartedAt > 0)
+
+	output = CheckCommand(t, "system", "maintenance", "off")
+	assert.Contains(t, output, "Maintenance mode turned off")
+
+	result = <-th.App.Srv.Store.System().Get()
+	require.Nil(t, result.Err)
+	_, ok := result.Data.(model.StringMap)[model.SYSTEM_MAINTENANCE_MODE]
+	assert.False(t, ok)
+}
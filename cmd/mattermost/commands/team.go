@@ -4,14 +4,23 @@
 package commands
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/mattermost/mattermost-server/app"
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/spf13/cobra"
 )
 
+// TEAM_DELETION_WAIT_INTERVAL is how often the progress of a team's deletion is checked with --wait.
+const TEAM_DELETION_WAIT_INTERVAL = time.Second
+
 var TeamCmd = &cobra.Command{
 	Use:   "team",
 	Short: "Management of teams",
@@ -20,9 +29,12 @@ var TeamCmd = &cobra.Command{
 var TeamCreateCmd = &cobra.Command{
 	Use:   "create",
 	Short: "Create a team",
-	Long:  `Create a team.`,
+	Long: `Create a team.
+With --template, the team is created from a JSON file describing the team along with its channels, the channels that new members join, its admins and its incoming webhooks, and --name and --display_name replace those in the file if they're given. If any of it fails, what was created is removed again. With --update, a team that already exists is brought in line with the template instead, so the same template can be applied again after it's changed.`,
 	Example: `  team create --name mynewteam --display_name "My New Team"
-  team create --name private --display_name "My New Private Team" --private`,
+  team create --name private --display_name "My New Private Team" --private
+  team create --template project.json --name apollo --display_name "Apollo"
+  team create --template project.json --name apollo --update`,
 	RunE: createTeamCmdF,
 }
 
@@ -46,26 +58,50 @@ var DeleteTeamsCmd = &cobra.Command{
 	Use:   "delete [teams]",
 	Short: "Delete teams",
 	Long: `Permanently delete some teams.
-Permanently deletes a team along with all related information including posts from the database.`,
-	Example: "  team delete myteam",
+Archives the teams and creates a job for each that permanently deletes it along with all related information, including channels, posts, files, webhooks, commands and members. The jobs are run by the server, and with --wait the command waits for them to finish while showing their progress.
+Running the command again for a team whose deletion failed, was canceled or was stopped by the server shutting down resumes the deletion from where it stopped.`,
+	Example: "  team delete myteam --wait",
 	RunE:    deleteTeamsCmdF,
 }
 
 var ListTeamsCmd = &cobra.Command{
 	Use:     "list",
 	Short:   "List all teams.",
-	Long:    `List all teams on the server.`,
-	Example: "  team list",
+	Long:    `List all teams on the server. With --counts, the number of active members of each team is shown along with the number of active users on the server, which counts users in more than one team once.`,
+	Example: "  team list --counts",
 	RunE:    listTeamsCmdF,
 }
 
+var InviteUsersCmd = &cobra.Command{
+	Use:   "invite [team]",
+	Short: "Invite users to team from a CSV file",
+	Long: `Send invitations to join a team to the people listed in a CSV file.
+Each row has an email address followed by an optional role (member or guest), space separated channel names that the invitee will be added to and a personal message.`,
+	Example: "  team invite myteam --file invites.csv --sender admin@example.com",
+	RunE:    inviteUsersCmdF,
+}
+
 func init() {
 	TeamCreateCmd.Flags().String("name", "", "Team Name")
 	TeamCreateCmd.Flags().String("display_name", "", "Team Display Name")
 	TeamCreateCmd.Flags().Bool("private", false, "Create a private team.")
 	TeamCreateCmd.Flags().String("email", "", "Administrator Email (anyone with this email is automatically a team admin)")
+	TeamCreateCmd.Flags().String("template", "", "JSON file with a team template to create the team from")
+	TeamCreateCmd.Flags().Bool("update", false, "Apply the template to the team if it already exists")
 
 	DeleteTeamsCmd.Flags().Bool("confirm", false, "Confirm you really want to delete the team and a DB backup has been performed.")
+	DeleteTeamsCmd.Flags().Bool("wait", false, "Wait for the teams to be deleted, showing the progress of each.")
+
+	ListTeamsCmd.Flags().Bool("counts", false, "Show the number of active members of each team.")
+	AddOutputFlag(ListTeamsCmd)
+
+	InviteUsersCmd.Flags().String("file", "", "CSV file of invitations")
+	InviteUsersCmd.Flags().String("sender", "", "Username or email of the user sending the invitations")
+
+	withLocalMode(TeamCreateCmd, createTeamLocalCmdF)
+	withLocalMode(RemoveUsersCmd, removeUsersLocalCmdF)
+	withLocalMode(AddUsersCmd, addUsersLocalCmdF)
+	withLocalMode(ListTeamsCmd, listTeamsLocalCmdF)
 
 	TeamCmd.AddCommand(
 		TeamCreateCmd,
@@ -73,6 +109,7 @@ func init() {
 		AddUsersCmd,
 		DeleteTeamsCmd,
 		ListTeamsCmd,
+		InviteUsersCmd,
 	)
 	RootCmd.AddCommand(TeamCmd)
 }
@@ -84,13 +121,99 @@ func createTeamCmdF(command *cobra.Command, args []string) error {
 	}
 	defer a.Shutdown()
 
+	if templateFile, _ := command.Flags().GetString("template"); templateFile != "" {
+		template, err := getTeamTemplateFromCreateFlags(command, templateFile)
+		if err != nil {
+			return err
+		}
+		update, _ := command.Flags().GetBool("update")
+
+		team, appErr := a.CreateTeamFromTemplate(template, "", update)
+		if appErr != nil {
+			return errors.New("Team creation failed: " + appErr.Error())
+		}
+
+		CommandPrettyPrintln("Applied the template to the team " + team.Name)
+		return nil
+	}
+
+	team, err := getTeamFromCreateFlags(command)
+	if err != nil {
+		return err
+	}
+
+	if _, err := a.CreateTeam(team); err != nil {
+		return errors.New("Team creation failed: " + err.Error())
+	}
+
+	return nil
+}
+
+func createTeamLocalCmdF(client *model.Client4, command *cobra.Command, args []string) error {
+	if templateFile, _ := command.Flags().GetString("template"); templateFile != "" {
+		template, err := getTeamTemplateFromCreateFlags(command, templateFile)
+		if err != nil {
+			return err
+		}
+		update, _ := command.Flags().GetBool("update")
+
+		team, resp := client.CreateTeamFromTemplate(template, update)
+		if resp.Error != nil {
+			return errors.New("Team creation failed: " + resp.Error.Error())
+		}
+
+		CommandPrettyPrintln("Applied the template to the team " + team.Name)
+		return nil
+	}
+
+	team, err := getTeamFromCreateFlags(command)
+	if err != nil {
+		return err
+	}
+
+	if _, resp := client.CreateTeam(team); resp.Error != nil {
+		return errors.New("Team creation failed: " + resp.Error.Error())
+	}
+
+	return nil
+}
+
+// getTeamTemplateFromCreateFlags reads the team template in the file, replacing the name and display name of its team
+// with those in the flags if they're given.
+func getTeamTemplateFromCreateFlags(command *cobra.Command, templateFile string) (*model.TeamTemplate, error) {
+	file, err := os.Open(templateFile)
+	if err != nil {
+		return nil, errors.New("Unable to open the template file: " + err.Error())
+	}
+	defer file.Close()
+
+	var template *model.TeamTemplate
+	if err := json.NewDecoder(file).Decode(&template); err != nil || template == nil {
+		return nil, errors.New("Unable to read the template file, it must be a JSON team template")
+	}
+
+	if template.Team == nil {
+		template.Team = &model.Team{}
+	}
+
+	if name, _ := command.Flags().GetString("name"); name != "" {
+		template.Team.Name = name
+	}
+	if displayName, _ := command.Flags().GetString("display_name"); displayName != "" {
+		template.Team.DisplayName = displayName
+	}
+
+	return template, nil
+}
+
+func getTeamFromCreateFlags(command *cobra.Command) (*model.Team, error) {
 	name, errn := command.Flags().GetString("name")
 	if errn != nil || name == "" {
-		return errors.New("Name is required")
+		return nil, errors.New("Name is required")
 	}
 	displayname, errdn := command.Flags().GetString("display_name")
 	if errdn != nil || displayname == "" {
-		return errors.New("Display Name is required")
+		return nil, errors.New("Display Name is required")
 	}
 	email, _ := command.Flags().GetString("email")
 	useprivate, _ := command.Flags().GetBool("private")
@@ -107,11 +230,7 @@ func createTeamCmdF(command *cobra.Command, args []string) error {
 		Type:        teamType,
 	}
 
-	if _, err := a.CreateTeam(team); err != nil {
-		return errors.New("Team creation failed: " + err.Error())
-	}
-
-	return nil
+	return team, nil
 }
 
 func removeUsersCmdF(command *cobra.Command, args []string) error {
@@ -148,6 +267,30 @@ func removeUserFromTeam(a *app.App, team *model.Team, user *model.User, userArg
 	}
 }
 
+func removeUsersLocalCmdF(client *model.Client4, command *cobra.Command, args []string) error {
+	if len(args) < 2 {
+		return errors.New("Not enough arguments.")
+	}
+
+	team := getTeamFromTeamArgLocal(client, args[0])
+	if team == nil {
+		return errors.New("Unable to find team '" + args[0] + "'")
+	}
+
+	users := getUsersFromUserArgsLocal(client, args[1:])
+	for i, user := range users {
+		if user == nil {
+			CommandPrintErrorln("Can't find user '" + args[i+1] + "'")
+			continue
+		}
+		if _, resp := client.RemoveTeamMember(team.Id, user.Id); resp.Error != nil {
+			CommandPrintErrorln("Unable to remove '" + args[i+1] + "' from " + team.Name + ". Error: " + resp.Error.Error())
+		}
+	}
+
+	return nil
+}
+
 func addUsersCmdF(command *cobra.Command, args []string) error {
 	a, err := InitDBCommandContextCobra(command)
 	if err != nil {
@@ -182,6 +325,30 @@ func addUserToTeam(a *app.App, team *model.Team, user *model.User, userArg strin
 	}
 }
 
+func addUsersLocalCmdF(client *model.Client4, command *cobra.Command, args []string) error {
+	if len(args) < 2 {
+		return errors.New("Not enough arguments.")
+	}
+
+	team := getTeamFromTeamArgLocal(client, args[0])
+	if team == nil {
+		return errors.New("Unable to find team '" + args[0] + "'")
+	}
+
+	users := getUsersFromUserArgsLocal(client, args[1:])
+	for i, user := range users {
+		if user == nil {
+			CommandPrintErrorln("Can't find user '" + args[i+1] + "'")
+			continue
+		}
+		if _, resp := client.AddTeamMember(team.Id, user.Id); resp.Error != nil {
+			CommandPrintErrorln("Unable to add '" + args[i+1] + "' to " + team.Name)
+		}
+	}
+
+	return nil
+}
+
 func deleteTeamsCmdF(command *cobra.Command, args []string) error {
 	a, err := InitDBCommandContextCobra(command)
 	if err != nil {
@@ -209,13 +376,27 @@ func deleteTeamsCmdF(command *cobra.Command, args []string) error {
 		}
 	}
 
+	wait, _ := command.Flags().GetBool("wait")
+
 	teams := getTeamsFromTeamArgs(a, args)
 	for i, team := range teams {
 		if team == nil {
 			CommandPrintErrorln("Unable to find team '" + args[i] + "'")
 			continue
 		}
-		if err := deleteTeam(a, team); err != nil {
+
+		job, err := a.MarkTeamForPermanentDeletion(team)
+		if err != nil {
+			CommandPrintErrorln("Unable to delete team '" + team.Name + "' error: " + err.Error())
+			continue
+		}
+		CommandPrettyPrintln("Team '" + team.Name + "' will be permanently deleted by job " + job.Id)
+
+		if !wait {
+			continue
+		}
+
+		if err := waitForTeamDeletionJob(a, job); err != nil {
 			CommandPrintErrorln("Unable to delete team '" + team.Name + "' error: " + err.Error())
 		} else {
 			CommandPrettyPrintln("Deleted team '" + team.Name + "'")
@@ -225,8 +406,33 @@ func deleteTeamsCmdF(command *cobra.Command, args []string) error {
 	return nil
 }
 
-func deleteTeam(a *app.App, team *model.Team) *model.AppError {
-	return a.PermanentDeleteTeam(team)
+// waitForTeamDeletionJob polls the job until it's finished, showing its progress, and returns an error if it didn't
+// succeed.
+func waitForTeamDeletionJob(a *app.App, job *model.Job) error {
+	for {
+		current, err := a.Jobs.GetJob(job.Id)
+		if err != nil {
+			return err
+		}
+
+		switch current.Status {
+		case model.JOB_STATUS_SUCCESS:
+			CommandPrintProgress(100, model.TEAM_DELETION_STAGE_DONE, true)
+			return nil
+		case model.JOB_STATUS_ERROR:
+			CommandPrintProgress(current.Progress, "failed", true)
+			return errors.New(current.Data["error"])
+		case model.JOB_STATUS_CANCELED:
+			CommandPrintProgress(current.Progress, "canceled", true)
+			return errors.New("The job was canceled. Run the command again to resume the deletion.")
+		case model.JOB_STATUS_PENDING:
+			CommandPrintProgress(0, "waiting for the job to be run by the server", false)
+		default:
+			CommandPrintProgress(current.Progress, model.TeamDeletionProgressFromJobData(current.Data).Stage, false)
+		}
+
+		time.Sleep(TEAM_DELETION_WAIT_INTERVAL)
+	}
 }
 
 func listTeamsCmdF(command *cobra.Command, args []string) error {
@@ -236,13 +442,248 @@ func listTeamsCmdF(command *cobra.Command, args []string) error {
 	}
 	defer a.Shutdown()
 
+	printer, err := NewPrinter(command)
+	if err != nil {
+		return err
+	}
+
 	teams, err2 := a.GetAllTeams()
 	if err2 != nil {
 		return err2
 	}
 
+	counts, _ := command.Flags().GetBool("counts")
+	if !counts {
+		records := make([]*teamRecord, 0, len(teams))
+		for _, team := range teams {
+			records = append(records, newTeamRecord(team))
+		}
+		return printer.PrintRecords(records)
+	}
+
+	records := make([]*teamCountRecord, 0, len(teams))
 	for _, team := range teams {
-		CommandPrettyPrintln(team.Name)
+		records = append(records, &teamCountRecord{
+			Id:                team.Id,
+			Name:              team.Name,
+			ActiveMemberCount: team.MemberCount,
+		})
+	}
+	if err := printer.PrintRecords(records); err != nil {
+		return err
+	}
+
+	report, err2 := a.GetSeatReport()
+	if err2 != nil {
+		return err2
+	}
+	printer.PrintMessage(seatReportTotal(report))
+
+	return nil
+}
+
+func listTeamsLocalCmdF(client *model.Client4, command *cobra.Command, args []string) error {
+	printer, err := NewPrinter(command)
+	if err != nil {
+		return err
+	}
+
+	counts, _ := command.Flags().GetBool("counts")
+	if counts {
+		// The seat report is used since the counts aren't sent with the teams
+		report, resp := client.GetSeatReport()
+		if resp.Error != nil {
+			return errors.New("Unable to get the teams. Error: " + resp.Error.Error())
+		}
+
+		records := make([]*teamCountRecord, 0, len(report.Teams))
+		for _, team := range report.Teams {
+			records = append(records, &teamCountRecord{
+				Id:                team.TeamId,
+				Name:              team.Name,
+				ActiveMemberCount: team.ActiveMemberCount,
+			})
+		}
+		if err := printer.PrintRecords(records); err != nil {
+			return err
+		}
+
+		printer.PrintMessage(seatReportTotal(report))
+
+		return nil
+	}
+
+	records := []*teamRecord{}
+	for page := 0; ; page++ {
+		teams, resp := client.GetAllTeams("", page, LOCAL_MODE_PAGE_SIZE)
+		if resp.Error != nil {
+			return errors.New("Unable to get the teams. Error: " + resp.Error.Error())
+		}
+
+		for _, team := range teams {
+			records = append(records, newTeamRecord(team))
+		}
+
+		if len(teams) < LOCAL_MODE_PAGE_SIZE {
+			break
+		}
+	}
+
+	return printer.PrintRecords(records)
+}
+
+func seatReportTotal(report *model.SeatReport) string {
+	return fmt.Sprintf("Total: %v active users, %v bots, %v deactivated users", report.ActiveUserCount, report.BotCount, report.DeactivatedUserCount)
+}
+
+// teamRecord is how a team is printed by team list.
+type teamRecord struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Type        string `json:"type"`
+}
+
+func newTeamRecord(team *model.Team) *teamRecord {
+	return &teamRecord{
+		Id:          team.Id,
+		Name:        team.Name,
+		DisplayName: team.DisplayName,
+		Type:        team.Type,
+	}
+}
+
+func (r *teamRecord) String() string {
+	return r.Name
+}
+
+// teamCountRecord is how a team is printed by team list --counts.
+type teamCountRecord struct {
+	Id                string `json:"id"`
+	Name              string `json:"name"`
+	ActiveMemberCount int64  `json:"active_member_count"`
+}
+
+func (r *teamCountRecord) String() string {
+	return fmt.Sprintf("%v: %v active members", r.Name, r.ActiveMemberCount)
+}
+
+// inviteRow is an invitation read from a CSV file, which names the channels instead of using their
+// ids.
+type inviteRow struct {
+	Email        string
+	Role         string
+	ChannelNames []string
+	Message      string
+}
+
+// parseInvitesCsv reads the rows of an invitation file, skipping a header row if there is one.
+func parseInvitesCsv(data io.Reader) ([]*inviteRow, error) {
+	reader := csv.NewReader(data)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []*inviteRow{}
+	for i, record := range records {
+		field := func(index int) string {
+			if index < len(record) {
+				return strings.TrimSpace(record[index])
+			}
+			return ""
+		}
+
+		if field(0) == "" || (i == 0 && strings.EqualFold(field(0), "email")) {
+			continue
+		}
+
+		rows = append(rows, &inviteRow{
+			Email:        field(0),
+			Role:         strings.ToLower(field(1)),
+			ChannelNames: strings.Fields(field(2)),
+			Message:      field(3),
+		})
+	}
+
+	return rows, nil
+}
+
+func inviteUsersCmdF(command *cobra.Command, args []string) error {
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	if len(args) < 1 {
+		return errors.New("Not enough arguments.")
+	}
+
+	team := getTeamFromTeamArg(a, args[0])
+	if team == nil {
+		return errors.New("Unable to find team '" + args[0] + "'")
+	}
+
+	senderArg, _ := command.Flags().GetString("sender")
+	sender := getUserFromUserArg(a, senderArg)
+	if sender == nil {
+		return errors.New("Unable to find sender '" + senderArg + "'")
+	}
+
+	path, _ := command.Flags().GetString("file")
+	if path == "" {
+		return errors.New("File is required")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	rows, err := parseInvitesCsv(file)
+	if err != nil {
+		return errors.New("Unable to read '" + path + "': " + err.Error())
+	}
+
+	invites := []*model.Invite{}
+	for _, row := range rows {
+		invite := &model.Invite{Email: row.Email, Role: row.Role, Channels: []string{}, Message: row.Message}
+
+		for _, channelName := range row.ChannelNames {
+			channel, err := a.GetChannelByName(channelName, team.Id)
+			if err != nil {
+				CommandPrintErrorln("Unable to invite '" + row.Email + "'. Can't find channel '" + channelName + "'")
+				invite = nil
+				break
+			}
+			invite.Channels = append(invite.Channels, channel.Id)
+		}
+
+		if invite != nil {
+			invites = append(invites, invite)
+		}
+	}
+
+	if len(invites) == 0 {
+		return errors.New("No one to invite.")
+	}
+
+	results, appErr := a.SendTeamInvites(team.Id, invites, sender.Id)
+	if appErr != nil {
+		return appErr
+	}
+
+	for _, result := range results {
+		if result.Error != nil {
+			CommandPrintErrorln("Unable to invite '" + result.Email + "'. Error: " + result.Error.Error())
+		} else {
+			CommandPrettyPrintln("Invited '" + result.Email + "'")
+		}
 	}
 
 	return nil
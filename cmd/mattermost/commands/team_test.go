@@ -4,9 +4,15 @@
 package commands
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
 	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"github.com/mattermost/mattermost-server/api4"
 	"github.com/mattermost/mattermost-server/model"
 )
@@ -28,6 +34,47 @@ func TestCreateTeam(t *testing.T) {
 	}
 }
 
+func TestCreateTeamFromTemplate(t *testing.T) {
+	th := api4.Setup().InitBasic().InitSystemAdmin()
+	defer th.TearDown()
+
+	dir, err := ioutil.TempDir("", "team-template")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	template := &model.TeamTemplate{
+		Team:        &model.Team{DisplayName: "Project", Type: model.TEAM_INVITE},
+		Channels:    []*model.TeamTemplateChannel{{Name: "standup", DisplayName: "Standup", Type: model.CHANNEL_OPEN, Header: "Every day at 9"}},
+		AdminEmails: []string{th.BasicUser.Email},
+	}
+	templateFile := dir + "/project.json"
+	require.Nil(t, ioutil.WriteFile(templateFile, []byte(template.ToJson()), 0600))
+
+	name := "apollo" + model.NewId()
+	CheckCommand(t, "team", "create", "--template", templateFile, "--name", name, "--display_name", "Apollo")
+
+	team, resp := th.SystemAdminClient.GetTeamByName(name, "")
+	require.Nil(t, resp.Error)
+	assert.Equal(t, "Apollo", team.DisplayName)
+	assert.Equal(t, model.TEAM_INVITE, team.Type)
+
+	channel, resp := th.SystemAdminClient.GetChannelByName("standup", team.Id, "")
+	require.Nil(t, resp.Error)
+	assert.Equal(t, "Every day at 9", channel.Header)
+
+	require.Error(t, RunCommand(t, "team", "create", "--template", templateFile, "--name", name), "the team already exists")
+
+	template.Channels[0].Header = "Every day at 10"
+	require.Nil(t, ioutil.WriteFile(templateFile, []byte(template.ToJson()), 0600))
+	CheckCommand(t, "team", "create", "--template", templateFile, "--name", name, "--update")
+
+	channel, resp = th.SystemAdminClient.GetChannelByName("standup", team.Id, "")
+	require.Nil(t, resp.Error)
+	assert.Equal(t, "Every day at 10", channel.Header)
+
+	require.Error(t, RunCommand(t, "team", "create", "--template", dir+"/missing.json", "--name", name))
+}
+
 func TestJoinTeam(t *testing.T) {
 	th := api4.Setup().InitSystemAdmin().InitBasic()
 	defer th.TearDown()
@@ -79,6 +126,27 @@ func TestLeaveTeam(t *testing.T) {
 	}
 }
 
+func TestDeleteTeams(t *testing.T) {
+	th := api4.Setup().InitBasic()
+	defer th.TearDown()
+
+	team := th.CreateTeam()
+
+	// The command only makes the job, since it's run by the server's job workers
+	output := CheckCommand(t, "team", "delete", team.Name, "--confirm")
+	assert.Contains(t, output, "will be permanently deleted by job")
+
+	deleted, err := th.App.GetTeam(team.Id)
+	require.Nil(t, err)
+	assert.NotEqual(t, int64(0), deleted.DeleteAt, "the team should be archived")
+
+	job, err := th.App.MarkTeamForPermanentDeletion(deleted)
+	require.Nil(t, err)
+	assert.Equal(t, model.JOB_STATUS_PENDING, job.Status)
+	assert.Equal(t, team.Id, job.Data["team_id"])
+	assert.Contains(t, output, job.Id, "the pending job should be reused")
+}
+
 func TestListTeams(t *testing.T) {
 	th := api4.Setup().InitBasic()
 	defer th.TearDown()
@@ -94,4 +162,81 @@ func TestListTeams(t *testing.T) {
 	if !strings.Contains(string(output), name) {
 		t.Fatal("should have the created team")
 	}
+
+	output = CheckCommand(t, "team", "list", "--output", "table")
+	if !strings.Contains(output, "DISPLAY NAME") || !strings.Contains(output, displayName) {
+		t.Fatal("should list the teams as a table")
+	}
+}
+
+func TestListTeamsWithCounts(t *testing.T) {
+	th := api4.Setup().InitBasic()
+	defer th.TearDown()
+
+	stats, err := th.App.GetTeamStats(th.BasicTeam.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := CheckCommand(t, "team", "list", "--counts")
+
+	if !strings.Contains(output, fmt.Sprintf("%v: %v active members", th.BasicTeam.Name, stats.ActiveMemberCount)) {
+		t.Fatal("should have the number of members of the team")
+	}
+
+	if !strings.Contains(output, "Total: ") {
+		t.Fatal("should have the total number of users")
+	}
+}
+
+func TestParseInvitesCsv(t *testing.T) {
+	rows, err := parseInvitesCsv(strings.NewReader(`email,role,channels,message
+success+1@simulator.amazonses.com
+success+2@simulator.amazonses.com, Guest, town-square off-topic, "Welcome, friend"
+
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %v", len(rows))
+	}
+
+	if rows[0].Email != "success+1@simulator.amazonses.com" || rows[0].Role != "" || len(rows[0].ChannelNames) != 0 {
+		t.Fatal("wrong first row", rows[0])
+	}
+
+	if rows[1].Role != model.INVITE_ROLE_GUEST || strings.Join(rows[1].ChannelNames, ",") != "town-square,off-topic" || rows[1].Message != "Welcome, friend" {
+		t.Fatal("wrong second row", rows[1])
+	}
+
+	if _, err := parseInvitesCsv(strings.NewReader(`"unterminated`)); err == nil {
+		t.Fatal("should fail to parse an invalid file")
+	}
+}
+
+func TestInviteUsers(t *testing.T) {
+	th := api4.Setup().InitBasic()
+	defer th.TearDown()
+
+	file, err := ioutil.TempFile("", "invites")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	email := th.GenerateTestEmail()
+	file.WriteString(email + ",member," + th.BasicChannel.Name + "\n" + th.BasicUser2.Email + "\n")
+	file.Close()
+
+	output := CheckCommand(t, "team", "invite", th.BasicTeam.Name, "--file", file.Name(), "--sender", th.BasicUser.Email)
+
+	if !strings.Contains(output, "Invited '"+email+"'") {
+		t.Fatal("should have invited the new user", output)
+	}
+
+	if strings.Contains(output, "Invited '"+th.BasicUser2.Email+"'") {
+		t.Fatal("shouldn't invite a member of the team", output)
+	}
 }
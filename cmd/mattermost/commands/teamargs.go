@@ -31,3 +31,15 @@ func getTeamFromTeamArg(a *app.App, teamArg string) *model.Team {
 
 	return team
 }
+
+func getTeamFromTeamArgLocal(client *model.Client4, teamArg string) *model.Team {
+	if team, resp := client.GetTeamByName(teamArg, ""); resp.Error == nil {
+		return team
+	}
+
+	if team, resp := client.GetTeam(teamArg, ""); resp.Error == nil {
+		return team
+	}
+
+	return nil
+}
@@ -152,7 +152,30 @@ var SearchUserCmd = &cobra.Command{
 	RunE:    searchUserCmdF,
 }
 
+var ListUsersCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List users",
+	Long: `List users, optionally filtered by team, role, authentication method, activity and when they were created.
+Times can be given as a date (2006-01-02) or in RFC 3339 format (2006-01-02T15:04:05Z).`,
+	Example: `  user list --auth-service ldap --inactive
+  user list --team myteam --last-seen-before 2018-06-01`,
+	RunE: listUsersCmdF,
+}
+
 func init() {
+	ListUsersCmd.Flags().String("team", "", "Only list members of this team.")
+	ListUsersCmd.Flags().String("role", "", "Only list users with this system role, such as system_admin.")
+	ListUsersCmd.Flags().String("auth-service", "", "Only list users that sign in with this service: email, gitlab, ldap, saml, google or office365.")
+	ListUsersCmd.Flags().Bool("active", false, "Only list active users.")
+	ListUsersCmd.Flags().Bool("inactive", false, "Only list deactivated users.")
+	ListUsersCmd.Flags().String("created-after", "", "Only list users created at or after this time.")
+	ListUsersCmd.Flags().String("created-before", "", "Only list users created before this time.")
+	ListUsersCmd.Flags().String("last-seen-after", "", "Only list users last seen at or after this time.")
+	ListUsersCmd.Flags().String("last-seen-before", "", "Only list users last seen before this time, including users that have never been seen.")
+	ListUsersCmd.Flags().Int("page", 0, "Page number to fetch.")
+	ListUsersCmd.Flags().Int("per-page", 200, "Number of users to fetch per page.")
+	AddOutputFlag(ListUsersCmd)
+
 	UserCreateCmd.Flags().String("username", "", "Required. Username for the new user account.")
 	UserCreateCmd.Flags().String("email", "", "Required. The email address for the new user account.")
 	UserCreateCmd.Flags().String("password", "", "Required. The password for the new user account.")
@@ -230,6 +253,11 @@ Global Flags:
 {{.InheritedFlags.FlagUsages | trimTrailingWhitespaces}}
 `)
 
+	withLocalMode(UserActivateCmd, userActivateLocalCmdF)
+	withLocalMode(UserDeactivateCmd, userDeactivateLocalCmdF)
+	withLocalMode(UserCreateCmd, userCreateLocalCmdF)
+	withLocalMode(SearchUserCmd, searchUserLocalCmdF)
+
 	UserCmd.AddCommand(
 		UserActivateCmd,
 		UserDeactivateCmd,
@@ -243,6 +271,7 @@ Global Flags:
 		MigrateAuthCmd,
 		VerifyUserCmd,
 		SearchUserCmd,
+		ListUsersCmd,
 	)
 	RootCmd.AddCommand(UserCmd)
 }
@@ -263,6 +292,16 @@ func userActivateCmdF(command *cobra.Command, args []string) error {
 	return nil
 }
 
+func userActivateLocalCmdF(client *model.Client4, command *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return errors.New("Expected at least one argument. See help text for details.")
+	}
+
+	changeUsersActiveStatusLocal(client, args, true)
+
+	return nil
+}
+
 func changeUsersActiveStatus(a *app.App, userArgs []string, active bool) {
 	users := getUsersFromUserArgs(a, userArgs)
 	for i, user := range users {
@@ -288,6 +327,22 @@ func changeUserActiveStatus(a *app.App, user *model.User, userArg string, activa
 	return nil
 }
 
+func changeUsersActiveStatusLocal(client *model.Client4, userArgs []string, active bool) {
+	users := getUsersFromUserArgsLocal(client, userArgs)
+	for i, user := range users {
+		if user == nil {
+			CommandPrintErrorln(fmt.Sprintf("Can't find user '%v'", userArgs[i]))
+			continue
+		}
+		if user.IsSSOUser() {
+			fmt.Println("You must also deactivate this user in the SSO provider or they will be reactivated on next login or sync.")
+		}
+		if _, resp := client.UpdateUserActive(user.Id, active); resp.Error != nil {
+			CommandPrintErrorln(fmt.Sprintf("Unable to change activation status of user: %v", userArgs[i]))
+		}
+	}
+}
+
 func userDeactivateCmdF(command *cobra.Command, args []string) error {
 	a, err := InitDBCommandContextCobra(command)
 	if err != nil {
@@ -304,6 +359,16 @@ func userDeactivateCmdF(command *cobra.Command, args []string) error {
 	return nil
 }
 
+func userDeactivateLocalCmdF(client *model.Client4, command *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return errors.New("Expected at least one argument. See help text for details.")
+	}
+
+	changeUsersActiveStatusLocal(client, args, false)
+
+	return nil
+}
+
 func userCreateCmdF(command *cobra.Command, args []string) error {
 	a, err := InitDBCommandContextCobra(command)
 	if err != nil {
@@ -311,17 +376,56 @@ func userCreateCmdF(command *cobra.Command, args []string) error {
 	}
 	defer a.Shutdown()
 
+	user, systemAdmin, err := getUserFromCreateFlags(command)
+	if err != nil {
+		return err
+	}
+
+	if ruser, err := a.CreateUser(user); err != nil {
+		return errors.New("Unable to create user. Error: " + err.Error())
+	} else if systemAdmin {
+		a.UpdateUserRoles(ruser.Id, "system_user system_admin", false)
+	}
+
+	CommandPrettyPrintln("Created User")
+
+	return nil
+}
+
+func userCreateLocalCmdF(client *model.Client4, command *cobra.Command, args []string) error {
+	user, systemAdmin, err := getUserFromCreateFlags(command)
+	if err != nil {
+		return err
+	}
+
+	ruser, resp := client.CreateUser(user)
+	if resp.Error != nil {
+		return errors.New("Unable to create user. Error: " + resp.Error.Error())
+	}
+
+	if systemAdmin {
+		if _, resp := client.UpdateUserRoles(ruser.Id, "system_user system_admin"); resp.Error != nil {
+			return errors.New("Unable to make the user a system admin. Error: " + resp.Error.Error())
+		}
+	}
+
+	CommandPrettyPrintln("Created User")
+
+	return nil
+}
+
+func getUserFromCreateFlags(command *cobra.Command) (*model.User, bool, error) {
 	username, erru := command.Flags().GetString("username")
 	if erru != nil || username == "" {
-		return errors.New("Username is required")
+		return nil, false, errors.New("Username is required")
 	}
 	email, erre := command.Flags().GetString("email")
 	if erre != nil || email == "" {
-		return errors.New("Email is required")
+		return nil, false, errors.New("Email is required")
 	}
 	password, errp := command.Flags().GetString("password")
 	if errp != nil || password == "" {
-		return errors.New("Password is required")
+		return nil, false, errors.New("Password is required")
 	}
 	nickname, _ := command.Flags().GetString("nickname")
 	firstname, _ := command.Flags().GetString("firstname")
@@ -339,15 +443,7 @@ func userCreateCmdF(command *cobra.Command, args []string) error {
 		Locale:    locale,
 	}
 
-	if ruser, err := a.CreateUser(user); err != nil {
-		return errors.New("Unable to create user. Error: " + err.Error())
-	} else if systemAdmin {
-		a.UpdateUserRoles(ruser.Id, "system_user system_admin", false)
-	}
-
-	CommandPrettyPrintln("Created User")
-
-	return nil
+	return user, systemAdmin, nil
 }
 
 func userInviteCmdF(command *cobra.Command, args []string) error {
@@ -690,14 +786,28 @@ func searchUserCmdF(command *cobra.Command, args []string) error {
 		return errors.New("Expected at least one argument. See help text for details.")
 	}
 
-	users := getUsersFromUserArgs(a, args)
+	printSearchedUsers(getUsersFromUserArgs(a, args), args)
+
+	return nil
+}
+
+func searchUserLocalCmdF(client *model.Client4, command *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return errors.New("Expected at least one argument. See help text for details.")
+	}
 
+	printSearchedUsers(getUsersFromUserArgsLocal(client, args), args)
+
+	return nil
+}
+
+func printSearchedUsers(users []*model.User, userArgs []string) {
 	for i, user := range users {
 		if i > 0 {
 			CommandPrettyPrintln("------------------------------")
 		}
 		if user == nil {
-			CommandPrintErrorln("Unable to find user '" + args[i] + "'")
+			CommandPrintErrorln("Unable to find user '" + userArgs[i] + "'")
 			continue
 		}
 
@@ -710,6 +820,108 @@ func searchUserCmdF(command *cobra.Command, args []string) error {
 		CommandPrettyPrintln("email: " + user.Email)
 		CommandPrettyPrintln("auth_service: " + user.AuthService)
 	}
+}
+
+// userRecord is how a user is printed by user list.
+type userRecord struct {
+	Id          string `json:"id"`
+	Username    string `json:"username"`
+	Email       string `json:"email"`
+	Roles       string `json:"roles"`
+	AuthService string `json:"auth_service"`
+	Status      string `json:"status"`
+}
+
+func newUserRecord(user *model.User) *userRecord {
+	status := "active"
+	if user.DeleteAt > 0 {
+		status = "deactivated"
+	}
+
+	authService := user.AuthService
+	if authService == "" {
+		authService = model.USER_AUTH_SERVICE_EMAIL
+	}
+
+	return &userRecord{
+		Id:          user.Id,
+		Username:    user.Username,
+		Email:       user.Email,
+		Roles:       user.Roles,
+		AuthService: authService,
+		Status:      status,
+	}
+}
+
+func (r *userRecord) String() string {
+	return fmt.Sprintf("%v (%v) %v, %v", r.Username, r.Email, r.Id, r.Status)
+}
+
+func listUsersCmdF(command *cobra.Command, args []string) error {
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	filter := &model.UserFilter{}
+
+	if teamArg, _ := command.Flags().GetString("team"); teamArg != "" {
+		team := getTeamFromTeamArg(a, teamArg)
+		if team == nil {
+			return errors.New("Unable to find team '" + teamArg + "'")
+		}
+		filter.TeamId = team.Id
+	}
+
+	filter.Role, _ = command.Flags().GetString("role")
+	filter.AuthService, _ = command.Flags().GetString("auth-service")
+
+	active, _ := command.Flags().GetBool("active")
+	inactive, _ := command.Flags().GetBool("inactive")
+	if active && inactive {
+		return errors.New("Only one of --active and --inactive can be given.")
+	} else if active || inactive {
+		filter.Active = &active
+	}
+
+	for name, value := range map[string]*int64{
+		"created-after":    &filter.CreatedAfter,
+		"created-before":   &filter.CreatedBefore,
+		"last-seen-after":  &filter.LastSeenAfter,
+		"last-seen-before": &filter.LastSeenBefore,
+	} {
+		if *value, err = parseTimeFlag(command, name); err != nil {
+			return err
+		}
+	}
+
+	page, _ := command.Flags().GetInt("page")
+	perPage, _ := command.Flags().GetInt("per-page")
+
+	printer, err := NewPrinter(command)
+	if err != nil {
+		return err
+	}
+
+	users, appErr := a.GetUsersWithFilterPage(filter, page, perPage, true)
+	if appErr != nil {
+		return errors.New("Unable to list users. Error: " + appErr.Error())
+	}
+
+	count, appErr := a.GetUsersCountWithFilter(filter)
+	if appErr != nil {
+		return errors.New("Unable to count users. Error: " + appErr.Error())
+	}
+
+	records := make([]*userRecord, 0, len(users))
+	for _, user := range users {
+		records = append(records, newUserRecord(user))
+	}
+	if err := printer.PrintRecords(records); err != nil {
+		return err
+	}
+	printer.PrintMessage(fmt.Sprintf("Showing %v of %v users", len(users), count))
 
 	return nil
 }
@@ -0,0 +1,163 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/mattermost/mattermost-server/app"
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/spf13/cobra"
+)
+
+var UserDataExportCmd = &cobra.Command{
+	Use:     "dataexport",
+	Short:   "Export all data about a user",
+	Long:    "Export the profile, preferences, posts, reactions, uploaded files, sessions and audits of a user to a zip file.",
+	Example: "  user dataexport --email user@example.com -o user.zip",
+	RunE:    userDataExportCmdF,
+}
+
+var UserAnonymizeCmd = &cobra.Command{
+	Use:   "anonymize",
+	Short: "Remove the personal data of a user",
+	Long: `Replace the username, email and full name of a user with opaque values and remove their authentication data.
+The account and its posts are kept. Optionally, @mentions of the old username in posts are changed to the new one.`,
+	Example: "  user anonymize --email user@example.com --rewrite-mentions",
+	RunE:    userAnonymizeCmdF,
+}
+
+func init() {
+	UserDataExportCmd.Flags().String("email", "", "Required. The email address of the user to export.")
+	UserDataExportCmd.Flags().StringP("output", "o", "", "Required. The zip file to write the export to.")
+	UserDataExportCmd.Flags().Bool("dry-run", false, "Report how much data would be exported without writing the export.")
+	UserDataExportCmd.Flags().Bool("confirm", false, "Confirm you really want to export the personal data of the user.")
+
+	UserAnonymizeCmd.Flags().String("email", "", "Required. The email address of the user to anonymize.")
+	UserAnonymizeCmd.Flags().Bool("rewrite-mentions", false, "Change @mentions of the old username in posts to the new one.")
+	UserAnonymizeCmd.Flags().Bool("dry-run", false, "Report the changes that would be made without making them.")
+	UserAnonymizeCmd.Flags().Bool("confirm", false, "Confirm you really want to anonymize the user and a DB backup has been performed.")
+
+	UserCmd.AddCommand(
+		UserDataExportCmd,
+		UserAnonymizeCmd,
+	)
+}
+
+func getUserFromEmailFlag(a *app.App, command *cobra.Command) (*model.User, error) {
+	email, err := command.Flags().GetString("email")
+	if err != nil || email == "" {
+		return nil, errors.New("Email is required")
+	}
+
+	result := <-a.Srv.Store.User().GetByEmail(email)
+	if result.Err != nil {
+		return nil, errors.New("Unable to find user '" + email + "'")
+	}
+
+	return result.Data.(*model.User), nil
+}
+
+func userDataExportCmdF(command *cobra.Command, args []string) error {
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	user, err := getUserFromEmailFlag(a, command)
+	if err != nil {
+		return err
+	}
+
+	dryRun, _ := command.Flags().GetBool("dry-run")
+	if dryRun {
+		summary, appErr := a.ExportUserData(user.Id, nil)
+		if appErr != nil {
+			return appErr
+		}
+
+		CommandPrettyPrintln(fmt.Sprintf("Would export %d preferences, %d posts, %d reactions, %d files, %d sessions and %d audits", summary.Preferences, summary.Posts, summary.Reactions, summary.Files, summary.Sessions, summary.Audits))
+		return nil
+	}
+
+	output, _ := command.Flags().GetString("output")
+	if output == "" {
+		return errors.New("Output file is required")
+	}
+
+	confirmFlag, _ := command.Flags().GetBool("confirm")
+	if !confirmFlag {
+		var confirm string
+		CommandPrettyPrintln("The export will contain the personal data of " + user.Email + ". Are you sure you want to export it? (YES/NO): ")
+		fmt.Scanln(&confirm)
+		if confirm != "YES" {
+			return errors.New("ABORTED: You did not answer YES exactly, in all capitals.")
+		}
+	}
+
+	file, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return errors.New("Unable to create the output file: " + err.Error())
+	}
+	defer file.Close()
+
+	summary, appErr := a.ExportUserData(user.Id, file)
+	if appErr != nil {
+		file.Close()
+		os.Remove(output)
+		return appErr
+	}
+
+	CommandPrettyPrintln(fmt.Sprintf("Exported %d preferences, %d posts, %d reactions, %d files, %d sessions and %d audits to %s", summary.Preferences, summary.Posts, summary.Reactions, summary.Files, summary.Sessions, summary.Audits, output))
+
+	return nil
+}
+
+func userAnonymizeCmdF(command *cobra.Command, args []string) error {
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	user, err := getUserFromEmailFlag(a, command)
+	if err != nil {
+		return err
+	}
+
+	rewriteMentions, _ := command.Flags().GetBool("rewrite-mentions")
+	dryRun, _ := command.Flags().GetBool("dry-run")
+
+	confirmFlag, _ := command.Flags().GetBool("confirm")
+	if !confirmFlag && !dryRun {
+		var confirm string
+		CommandPrettyPrintln("Have you performed a database backup? (YES/NO): ")
+		fmt.Scanln(&confirm)
+
+		if confirm != "YES" {
+			return errors.New("ABORTED: You did not answer YES exactly, in all capitals.")
+		}
+		CommandPrettyPrintln("Are you sure you want to permanently remove the personal data of " + user.Email + "? (YES/NO): ")
+		fmt.Scanln(&confirm)
+		if confirm != "YES" {
+			return errors.New("ABORTED: You did not answer YES exactly, in all capitals.")
+		}
+	}
+
+	result, appErr := a.AnonymizeUser(user.Id, rewriteMentions, dryRun)
+	if appErr != nil {
+		return appErr
+	}
+
+	if dryRun {
+		CommandPrettyPrintln(fmt.Sprintf("Would rename '%s' to '%s' and rewrite mentions in %d posts", result.OldUsername, result.Username, result.PostsRewritten))
+	} else {
+		CommandPrettyPrintln(fmt.Sprintf("Renamed '%s' to '%s' and rewrote mentions in %d posts", result.OldUsername, result.Username, result.PostsRewritten))
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/api4"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestUserDataExport(t *testing.T) {
+	th := api4.Setup().InitBasic()
+	defer th.TearDown()
+
+	dir, err := ioutil.TempDir("", "user-data-export")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "export.zip")
+
+	require.Error(t, RunCommand(t, "user", "dataexport", "--confirm", "-o", output))
+	require.Error(t, RunCommand(t, "user", "dataexport", "--confirm", "--email", th.BasicUser.Email))
+
+	dryRun := CheckCommand(t, "user", "dataexport", "--dry-run", "--email", th.BasicUser.Email)
+	assert.Contains(t, dryRun, "Would export")
+	_, err = os.Stat(output)
+	assert.True(t, os.IsNotExist(err))
+
+	CheckCommand(t, "user", "dataexport", "--confirm", "--email", th.BasicUser.Email, "-o", output)
+	info, err := os.Stat(output)
+	require.Nil(t, err)
+	assert.True(t, info.Size() > 0)
+
+	require.Error(t, RunCommand(t, "user", "dataexport", "--confirm", "--email", th.BasicUser.Email, "-o", output), "shouldn't overwrite an existing file")
+}
+
+func TestUserAnonymize(t *testing.T) {
+	th := api4.Setup().InitBasic()
+	defer th.TearDown()
+
+	require.Error(t, RunCommand(t, "user", "anonymize", "--confirm"))
+
+	CheckCommand(t, "user", "anonymize", "--dry-run", "--email", th.BasicUser.Email)
+
+	result := <-th.App.Srv.Store.User().Get(th.BasicUser.Id)
+	require.Nil(t, result.Err)
+	assert.Equal(t, th.BasicUser.Username, result.Data.(*model.User).Username)
+
+	CheckCommand(t, "user", "anonymize", "--confirm", "--rewrite-mentions", "--email", th.BasicUser.Email)
+
+	result = <-th.App.Srv.Store.User().Get(th.BasicUser.Id)
+	require.Nil(t, result.Err)
+	user := result.Data.(*model.User)
+	assert.True(t, strings.HasPrefix(user.Username, model.USER_ANONYMIZED_USERNAME_PREFIX))
+	assert.True(t, strings.HasSuffix(user.Email, "@"+model.USER_ANONYMIZED_EMAIL_DOMAIN))
+}
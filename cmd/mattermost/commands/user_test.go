@@ -8,6 +8,7 @@ import (
 
 	"github.com/mattermost/mattermost-server/api4"
 	"github.com/mattermost/mattermost-server/model"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -119,3 +120,31 @@ func TestChangeUserEmail(t *testing.T) {
 	require.Error(t, RunCommand(t, "user", "email", th.BasicUser.Username, th.BasicUser2.Email))
 
 }
+
+func TestListUsers(t *testing.T) {
+	th := api4.Setup().InitBasic()
+	defer th.TearDown()
+
+	th.UpdateActiveUser(th.BasicUser2, false)
+
+	output := CheckCommand(t, "user", "list", "--team", th.BasicTeam.Name)
+	assert.Contains(t, output, th.BasicUser.Username)
+	assert.Contains(t, output, th.BasicUser2.Username+" ("+th.BasicUser2.Email+") "+th.BasicUser2.Id+", deactivated")
+
+	output = CheckCommand(t, "user", "list", "--team", th.BasicTeam.Name, "--inactive", "--auth-service", "email", "--last-seen-before", "2100-01-01")
+	assert.NotContains(t, output, th.BasicUser.Username)
+	assert.Contains(t, output, th.BasicUser2.Username)
+	assert.Contains(t, output, "Showing 1 of 1 users")
+
+	output = CheckCommand(t, "user", "list", "--team", th.BasicTeam.Name, "--active", "--per-page", "1", "--page", "1")
+	assert.Contains(t, output, "Showing 1 of ")
+
+	// should fail because both --active and --inactive are given
+	require.Error(t, RunCommand(t, "user", "list", "--active", "--inactive"))
+
+	// should fail because the date is invalid
+	require.Error(t, RunCommand(t, "user", "list", "--created-after", "yesterday"))
+
+	// should fail because the team does not exist
+	require.Error(t, RunCommand(t, "user", "list", "--team", "doesnotexist"))
+}
@@ -37,3 +37,28 @@ func getUserFromUserArg(a *app.App, userArg string) *model.User {
 
 	return user
 }
+
+func getUsersFromUserArgsLocal(client *model.Client4, userArgs []string) []*model.User {
+	users := make([]*model.User, 0, len(userArgs))
+	for _, userArg := range userArgs {
+		user := getUserFromUserArgLocal(client, userArg)
+		users = append(users, user)
+	}
+	return users
+}
+
+func getUserFromUserArgLocal(client *model.Client4, userArg string) *model.User {
+	if user, resp := client.GetUserByEmail(userArg, ""); resp.Error == nil {
+		return user
+	}
+
+	if user, resp := client.GetUserByUsername(userArg, ""); resp.Error == nil {
+		return user
+	}
+
+	if user, resp := client.GetUser(userArg, ""); resp.Error == nil {
+		return user
+	}
+
+	return nil
+}
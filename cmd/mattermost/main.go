@@ -11,6 +11,19 @@ import (
 	// Plugins
 	_ "github.com/mattermost/mattermost-server/model/gitlab"
 
+	// Team Edition Jobs
+	_ "github.com/mattermost/mattermost-server/analyticsrollup"
+	_ "github.com/mattermost/mattermost-server/channelarchiving"
+	_ "github.com/mattermost/mattermost-server/channeldeletion"
+	_ "github.com/mattermost/mattermost-server/channelmembercounts"
+	_ "github.com/mattermost/mattermost-server/deactivation"
+	_ "github.com/mattermost/mattermost-server/filestorageusage"
+	_ "github.com/mattermost/mattermost-server/filestoreverification"
+	_ "github.com/mattermost/mattermost-server/idledeactivation"
+	_ "github.com/mattermost/mattermost-server/preferencecleanup"
+	_ "github.com/mattermost/mattermost-server/retention"
+	_ "github.com/mattermost/mattermost-server/teamdeletion"
+
 	// Enterprise Imports
 	_ "github.com/mattermost/mattermost-server/imports"
 
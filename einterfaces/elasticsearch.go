@@ -12,7 +12,9 @@ import (
 type ElasticsearchInterface interface {
 	Start() *model.AppError
 	IndexPost(post *model.Post, teamId string) *model.AppError
-	SearchPosts(channels *model.ChannelList, searchParams []*model.SearchParams) ([]string, *model.AppError)
+	// SearchPosts returns the ids of the posts on a page of the results of a search, along with how many
+	// posts match it in total.
+	SearchPosts(channels *model.ChannelList, searchParams []*model.SearchParams, page, perPage int) ([]string, int64, *model.AppError)
 	DeletePost(post *model.Post) *model.AppError
 	TestConfig(cfg *model.Config) *model.AppError
 	PurgeIndexes() *model.AppError
@@ -0,0 +1,13 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package jobs
+
+import (
+	"github.com/mattermost/mattermost-server/model"
+)
+
+type ChannelArchivingJobInterface interface {
+	MakeWorker() model.Worker
+	MakeScheduler() model.Scheduler
+}
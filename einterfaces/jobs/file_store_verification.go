@@ -0,0 +1,13 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package jobs
+
+import (
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// FileStoreVerificationJobInterface only makes a worker, since verification is only run when an admin asks for it.
+type FileStoreVerificationJobInterface interface {
+	MakeWorker() model.Worker
+}
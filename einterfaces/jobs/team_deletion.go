@@ -0,0 +1,13 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package jobs
+
+import (
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// TeamDeletionJobInterface only makes a worker, since a team is only deleted when an admin asks for it.
+type TeamDeletionJobInterface interface {
+	MakeWorker() model.Worker
+}
@@ -31,16 +31,28 @@ type MetricsInterface interface {
 	IncrementMemCacheHitCounter(cacheName string)
 	IncrementMemCacheMissCounter(cacheName string)
 	IncrementMemCacheInvalidationCounter(cacheName string)
+	IncrementMemCacheEvictionCounter(cacheName string)
 	IncrementMemCacheMissCounterSession()
 	IncrementMemCacheHitCounterSession()
 	IncrementMemCacheInvalidationCounterSession()
 
 	IncrementWebsocketEvent(eventType string)
 	IncrementWebSocketBroadcast(eventType string)
+	SetWebsocketHubQueueDepth(hubIndex int, depth int)
+	IncrementWebsocketHubEventDropped(hubIndex int)
+	ObserveWebsocketConnectionQueueDepth(depth int)
+	IncrementWebsocketConnectionEventDropped()
+	IncrementWebsocketSlowClientDisconnect()
+	IncrementWebsocketConnectionReaped()
 
 	AddMemCacheHitCounter(cacheName string, amount float64)
 	AddMemCacheMissCounter(cacheName string, amount float64)
 
 	IncrementPostsSearchCounter()
 	ObservePostsSearchDuration(elapsed float64)
+
+	IncrementDatabaseHealthEvent(event string)
+
+	AddSessionsCleanedUp(reason string, count int64)
+	AddTableRowsTrimmed(table string, count int64)
 }
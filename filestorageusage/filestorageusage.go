@@ -0,0 +1,19 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package filestorageusage
+
+import (
+	"github.com/mattermost/mattermost-server/app"
+	tjobs "github.com/mattermost/mattermost-server/einterfaces/jobs"
+)
+
+type FileStorageUsageJobInterfaceImpl struct {
+	App *app.App
+}
+
+func init() {
+	app.RegisterJobsFileStorageUsageJobInterface(func(a *app.App) tjobs.FileStorageUsageJobInterface {
+		return &FileStorageUsageJobInterfaceImpl{a}
+	})
+}
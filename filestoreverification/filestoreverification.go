@@ -0,0 +1,19 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package filestoreverification
+
+import (
+	"github.com/mattermost/mattermost-server/app"
+	tjobs "github.com/mattermost/mattermost-server/einterfaces/jobs"
+)
+
+type FileStoreVerificationJobInterfaceImpl struct {
+	App *app.App
+}
+
+func init() {
+	app.RegisterJobsFileStoreVerificationJobInterface(func(a *app.App) tjobs.FileStoreVerificationJobInterface {
+		return &FileStoreVerificationJobInterfaceImpl{a}
+	})
+}
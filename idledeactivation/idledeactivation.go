@@ -0,0 +1,19 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package idledeactivation
+
+import (
+	"github.com/mattermost/mattermost-server/app"
+	tjobs "github.com/mattermost/mattermost-server/einterfaces/jobs"
+)
+
+type IdleUserDeactivationJobInterfaceImpl struct {
+	App *app.App
+}
+
+func init() {
+	app.RegisterJobsIdleUserDeactivationJobInterface(func(a *app.App) tjobs.IdleUserDeactivationJobInterface {
+		return &IdleUserDeactivationJobInterfaceImpl{a}
+	})
+}
@@ -107,6 +107,83 @@ func (watcher *Watcher) PollAndNotify() {
 					default:
 					}
 				}
+			} else if job.Type == model.JOB_TYPE_BASIC_RETENTION {
+				if watcher.workers.BasicRetention != nil {
+					select {
+					case watcher.workers.BasicRetention.JobChannel() <- *job:
+					default:
+					}
+				}
+			} else if job.Type == model.JOB_TYPE_USER_DEACTIVATION {
+				if watcher.workers.UserDeactivation != nil {
+					select {
+					case watcher.workers.UserDeactivation.JobChannel() <- *job:
+					default:
+					}
+				}
+			} else if job.Type == model.JOB_TYPE_PREFERENCE_CLEANUP {
+				if watcher.workers.PreferenceCleanup != nil {
+					select {
+					case watcher.workers.PreferenceCleanup.JobChannel() <- *job:
+					default:
+					}
+				}
+			} else if job.Type == model.JOB_TYPE_ANALYTICS_ROLLUP {
+				if watcher.workers.AnalyticsRollup != nil {
+					select {
+					case watcher.workers.AnalyticsRollup.JobChannel() <- *job:
+					default:
+					}
+				}
+			} else if job.Type == model.JOB_TYPE_IDLE_USER_DEACTIVATION {
+				if watcher.workers.IdleUserDeactivation != nil {
+					select {
+					case watcher.workers.IdleUserDeactivation.JobChannel() <- *job:
+					default:
+					}
+				}
+			} else if job.Type == model.JOB_TYPE_CHANNEL_MEMBER_COUNTS {
+				if watcher.workers.ChannelMemberCounts != nil {
+					select {
+					case watcher.workers.ChannelMemberCounts.JobChannel() <- *job:
+					default:
+					}
+				}
+			} else if job.Type == model.JOB_TYPE_CHANNEL_ARCHIVING {
+				if watcher.workers.ChannelArchiving != nil {
+					select {
+					case watcher.workers.ChannelArchiving.JobChannel() <- *job:
+					default:
+					}
+				}
+			} else if job.Type == model.JOB_TYPE_FILE_STORAGE_USAGE {
+				if watcher.workers.FileStorageUsage != nil {
+					select {
+					case watcher.workers.FileStorageUsage.JobChannel() <- *job:
+					default:
+					}
+				}
+			} else if job.Type == model.JOB_TYPE_CHANNEL_DELETION {
+				if watcher.workers.ChannelDeletion != nil {
+					select {
+					case watcher.workers.ChannelDeletion.JobChannel() <- *job:
+					default:
+					}
+				}
+			} else if job.Type == model.JOB_TYPE_FILE_STORE_VERIFICATION {
+				if watcher.workers.FileStoreVerification != nil {
+					select {
+					case watcher.workers.FileStoreVerification.JobChannel() <- *job:
+					default:
+					}
+				}
+			} else if job.Type == model.JOB_TYPE_TEAM_DELETION {
+				if watcher.workers.TeamDeletion != nil {
+					select {
+					case watcher.workers.TeamDeletion.JobChannel() <- *job:
+					default:
+					}
+				}
 			}
 		}
 	}
@@ -50,6 +50,42 @@ func (srv *JobServer) InitSchedulers() *Schedulers {
 		schedulers.schedulers = append(schedulers.schedulers, ldapSyncInterface.MakeScheduler())
 	}
 
+	if basicRetentionInterface := srv.BasicRetention; basicRetentionInterface != nil {
+		schedulers.schedulers = append(schedulers.schedulers, basicRetentionInterface.MakeScheduler())
+	}
+
+	if userDeactivationInterface := srv.UserDeactivation; userDeactivationInterface != nil {
+		schedulers.schedulers = append(schedulers.schedulers, userDeactivationInterface.MakeScheduler())
+	}
+
+	if preferenceCleanupInterface := srv.PreferenceCleanup; preferenceCleanupInterface != nil {
+		schedulers.schedulers = append(schedulers.schedulers, preferenceCleanupInterface.MakeScheduler())
+	}
+
+	if analyticsRollupInterface := srv.AnalyticsRollup; analyticsRollupInterface != nil {
+		schedulers.schedulers = append(schedulers.schedulers, analyticsRollupInterface.MakeScheduler())
+	}
+
+	if idleUserDeactivationInterface := srv.IdleUserDeactivation; idleUserDeactivationInterface != nil {
+		schedulers.schedulers = append(schedulers.schedulers, idleUserDeactivationInterface.MakeScheduler())
+	}
+
+	if channelMemberCountsInterface := srv.ChannelMemberCounts; channelMemberCountsInterface != nil {
+		schedulers.schedulers = append(schedulers.schedulers, channelMemberCountsInterface.MakeScheduler())
+	}
+
+	if channelArchivingInterface := srv.ChannelArchiving; channelArchivingInterface != nil {
+		schedulers.schedulers = append(schedulers.schedulers, channelArchivingInterface.MakeScheduler())
+	}
+
+	if fileStorageUsageInterface := srv.FileStorageUsage; fileStorageUsageInterface != nil {
+		schedulers.schedulers = append(schedulers.schedulers, fileStorageUsageInterface.MakeScheduler())
+	}
+
+	if channelDeletionInterface := srv.ChannelDeletion; channelDeletionInterface != nil {
+		schedulers.schedulers = append(schedulers.schedulers, channelDeletionInterface.MakeScheduler())
+	}
+
 	schedulers.nextRunTimes = make([]*time.Time, len(schedulers.schedulers))
 	return schedulers
 }
@@ -4,6 +4,8 @@
 package jobs
 
 import (
+	"sync"
+
 	ejobs "github.com/mattermost/mattermost-server/einterfaces/jobs"
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/store"
@@ -29,11 +31,24 @@ type JobServer struct {
 	Workers       *Workers
 	Schedulers    *Schedulers
 
+	workersLock sync.Mutex
+
 	DataRetentionJob        ejobs.DataRetentionJobInterface
 	MessageExportJob        ejobs.MessageExportJobInterface
 	ElasticsearchAggregator ejobs.ElasticsearchAggregatorInterface
 	ElasticsearchIndexer    ejobs.ElasticsearchIndexerInterface
 	LdapSync                ejobs.LdapSyncInterface
+	BasicRetention          ejobs.BasicRetentionJobInterface
+	UserDeactivation        ejobs.UserDeactivationJobInterface
+	PreferenceCleanup       ejobs.PreferenceCleanupJobInterface
+	AnalyticsRollup         ejobs.AnalyticsRollupJobInterface
+	IdleUserDeactivation    ejobs.IdleUserDeactivationJobInterface
+	ChannelMemberCounts     ejobs.ChannelMemberCountsJobInterface
+	ChannelArchiving        ejobs.ChannelArchivingJobInterface
+	FileStorageUsage        ejobs.FileStorageUsageJobInterface
+	ChannelDeletion         ejobs.ChannelDeletionJobInterface
+	FileStoreVerification   ejobs.FileStoreVerificationJobInterface
+	TeamDeletion            ejobs.TeamDeletionJobInterface
 }
 
 func NewJobServer(configService ConfigService, store store.Store) *JobServer {
@@ -48,6 +63,9 @@ func (srv *JobServer) Config() *model.Config {
 }
 
 func (srv *JobServer) StartWorkers() {
+	srv.workersLock.Lock()
+	defer srv.workersLock.Unlock()
+
 	srv.Workers = srv.InitWorkers().Start()
 }
 
@@ -55,9 +73,15 @@ func (srv *JobServer) StartSchedulers() {
 	srv.Schedulers = srv.InitSchedulers().Start()
 }
 
+// StopWorkers stops the workers once they've finished the jobs that they're running. The workers can be stopped
+// more than once, such as by draining the server before it's shut down.
 func (srv *JobServer) StopWorkers() {
+	srv.workersLock.Lock()
+	defer srv.workersLock.Unlock()
+
 	if srv.Workers != nil {
 		srv.Workers.Stop()
+		srv.Workers = nil
 	}
 }
 
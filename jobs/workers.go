@@ -20,6 +20,17 @@ type Workers struct {
 	ElasticsearchIndexing    model.Worker
 	ElasticsearchAggregation model.Worker
 	LdapSync                 model.Worker
+	BasicRetention           model.Worker
+	UserDeactivation         model.Worker
+	PreferenceCleanup        model.Worker
+	AnalyticsRollup          model.Worker
+	IdleUserDeactivation     model.Worker
+	ChannelMemberCounts      model.Worker
+	ChannelArchiving         model.Worker
+	FileStorageUsage         model.Worker
+	ChannelDeletion          model.Worker
+	FileStoreVerification    model.Worker
+	TeamDeletion             model.Worker
 
 	listenerId string
 }
@@ -50,6 +61,50 @@ func (srv *JobServer) InitWorkers() *Workers {
 		workers.LdapSync = ldapSyncInterface.MakeWorker()
 	}
 
+	if basicRetentionInterface := srv.BasicRetention; basicRetentionInterface != nil {
+		workers.BasicRetention = basicRetentionInterface.MakeWorker()
+	}
+
+	if userDeactivationInterface := srv.UserDeactivation; userDeactivationInterface != nil {
+		workers.UserDeactivation = userDeactivationInterface.MakeWorker()
+	}
+
+	if preferenceCleanupInterface := srv.PreferenceCleanup; preferenceCleanupInterface != nil {
+		workers.PreferenceCleanup = preferenceCleanupInterface.MakeWorker()
+	}
+
+	if analyticsRollupInterface := srv.AnalyticsRollup; analyticsRollupInterface != nil {
+		workers.AnalyticsRollup = analyticsRollupInterface.MakeWorker()
+	}
+
+	if idleUserDeactivationInterface := srv.IdleUserDeactivation; idleUserDeactivationInterface != nil {
+		workers.IdleUserDeactivation = idleUserDeactivationInterface.MakeWorker()
+	}
+
+	if channelMemberCountsInterface := srv.ChannelMemberCounts; channelMemberCountsInterface != nil {
+		workers.ChannelMemberCounts = channelMemberCountsInterface.MakeWorker()
+	}
+
+	if channelArchivingInterface := srv.ChannelArchiving; channelArchivingInterface != nil {
+		workers.ChannelArchiving = channelArchivingInterface.MakeWorker()
+	}
+
+	if fileStorageUsageInterface := srv.FileStorageUsage; fileStorageUsageInterface != nil {
+		workers.FileStorageUsage = fileStorageUsageInterface.MakeWorker()
+	}
+
+	if channelDeletionInterface := srv.ChannelDeletion; channelDeletionInterface != nil {
+		workers.ChannelDeletion = channelDeletionInterface.MakeWorker()
+	}
+
+	if fileStoreVerificationInterface := srv.FileStoreVerification; fileStoreVerificationInterface != nil {
+		workers.FileStoreVerification = fileStoreVerificationInterface.MakeWorker()
+	}
+
+	if teamDeletionInterface := srv.TeamDeletion; teamDeletionInterface != nil {
+		workers.TeamDeletion = teamDeletionInterface.MakeWorker()
+	}
+
 	return workers
 }
 
@@ -77,6 +132,50 @@ func (workers *Workers) Start() *Workers {
 			go workers.LdapSync.Run()
 		}
 
+		if workers.BasicRetention != nil && *workers.ConfigService.Config().BasicRetentionSettings.Enable {
+			go workers.BasicRetention.Run()
+		}
+
+		if workers.UserDeactivation != nil {
+			go workers.UserDeactivation.Run()
+		}
+
+		if workers.PreferenceCleanup != nil {
+			go workers.PreferenceCleanup.Run()
+		}
+
+		if workers.AnalyticsRollup != nil {
+			go workers.AnalyticsRollup.Run()
+		}
+
+		if workers.IdleUserDeactivation != nil {
+			go workers.IdleUserDeactivation.Run()
+		}
+
+		if workers.ChannelMemberCounts != nil {
+			go workers.ChannelMemberCounts.Run()
+		}
+
+		if workers.ChannelArchiving != nil {
+			go workers.ChannelArchiving.Run()
+		}
+
+		if workers.FileStorageUsage != nil {
+			go workers.FileStorageUsage.Run()
+		}
+
+		if workers.ChannelDeletion != nil {
+			go workers.ChannelDeletion.Run()
+		}
+
+		if workers.FileStoreVerification != nil {
+			go workers.FileStoreVerification.Run()
+		}
+
+		if workers.TeamDeletion != nil {
+			go workers.TeamDeletion.Run()
+		}
+
 		go workers.Watcher.Start()
 	})
 
@@ -125,6 +224,14 @@ func (workers *Workers) handleConfigChange(oldConfig *model.Config, newConfig *m
 			workers.LdapSync.Stop()
 		}
 	}
+
+	if workers.BasicRetention != nil {
+		if !*oldConfig.BasicRetentionSettings.Enable && *newConfig.BasicRetentionSettings.Enable {
+			go workers.BasicRetention.Run()
+		} else if *oldConfig.BasicRetentionSettings.Enable && !*newConfig.BasicRetentionSettings.Enable {
+			workers.BasicRetention.Stop()
+		}
+	}
 }
 
 func (workers *Workers) Stop() *Workers {
@@ -152,6 +259,50 @@ func (workers *Workers) Stop() *Workers {
 		workers.LdapSync.Stop()
 	}
 
+	if workers.BasicRetention != nil && *workers.ConfigService.Config().BasicRetentionSettings.Enable {
+		workers.BasicRetention.Stop()
+	}
+
+	if workers.UserDeactivation != nil {
+		workers.UserDeactivation.Stop()
+	}
+
+	if workers.PreferenceCleanup != nil {
+		workers.PreferenceCleanup.Stop()
+	}
+
+	if workers.AnalyticsRollup != nil {
+		workers.AnalyticsRollup.Stop()
+	}
+
+	if workers.IdleUserDeactivation != nil {
+		workers.IdleUserDeactivation.Stop()
+	}
+
+	if workers.ChannelMemberCounts != nil {
+		workers.ChannelMemberCounts.Stop()
+	}
+
+	if workers.ChannelArchiving != nil {
+		workers.ChannelArchiving.Stop()
+	}
+
+	if workers.FileStorageUsage != nil {
+		workers.FileStorageUsage.Stop()
+	}
+
+	if workers.ChannelDeletion != nil {
+		workers.ChannelDeletion.Stop()
+	}
+
+	if workers.FileStoreVerification != nil {
+		workers.FileStoreVerification.Stop()
+	}
+
+	if workers.TeamDeletion != nil {
+		workers.TeamDeletion.Stop()
+	}
+
 	mlog.Info("Stopped workers")
 
 	return workers
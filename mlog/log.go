@@ -28,6 +28,7 @@ type Field = zapcore.Field
 
 var Int64 = zap.Int64
 var Int = zap.Int
+var Float64 = zap.Float64
 var String = zap.String
 var Any = zap.Any
 var Err = zap.Error
@@ -16,13 +16,14 @@ const (
 )
 
 type AccessData struct {
-	ClientId     string `json:"client_id"`
-	UserId       string `json:"user_id"`
-	Token        string `json:"token"`
-	RefreshToken string `json:"refresh_token"`
-	RedirectUri  string `json:"redirect_uri"`
-	ExpiresAt    int64  `json:"expires_at"`
-	Scope        string `json:"scope"`
+	ClientId             string `json:"client_id"`
+	UserId               string `json:"user_id"`
+	Token                string `json:"token"`
+	RefreshToken         string `json:"refresh_token"`
+	PreviousRefreshToken string `json:"previous_refresh_token"`
+	RedirectUri          string `json:"redirect_uri"`
+	ExpiresAt            int64  `json:"expires_at"`
+	Scope                string `json:"scope"`
 }
 
 type AccessResponse struct {
@@ -33,6 +34,19 @@ type AccessResponse struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+// IntrospectionResponse describes an access or refresh token as defined by RFC 7662. Only Active is set for
+// tokens that aren't active.
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientId  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+}
+
 // IsValid validates the AccessData and returns an error if it isn't configured
 // correctly.
 func (ad *AccessData) IsValid() *AppError {
@@ -53,6 +67,10 @@ func (ad *AccessData) IsValid() *AppError {
 		return NewAppError("AccessData.IsValid", "model.access.is_valid.refresh_token.app_error", nil, "", http.StatusBadRequest)
 	}
 
+	if len(ad.PreviousRefreshToken) > 26 {
+		return NewAppError("AccessData.IsValid", "model.access.is_valid.previous_refresh_token.app_error", nil, "", http.StatusBadRequest)
+	}
+
 	if len(ad.RedirectUri) == 0 || len(ad.RedirectUri) > 256 || !IsValidHttpUrl(ad.RedirectUri) {
 		return NewAppError("AccessData.IsValid", "model.access.is_valid.redirect_uri.app_error", nil, "", http.StatusBadRequest)
 	}
@@ -94,3 +112,14 @@ func AccessResponseFromJson(data io.Reader) *AccessResponse {
 	json.NewDecoder(data).Decode(&ar)
 	return ar
 }
+
+func (ir *IntrospectionResponse) ToJson() string {
+	b, _ := json.Marshal(ir)
+	return string(b)
+}
+
+func IntrospectionResponseFromJson(data io.Reader) *IntrospectionResponse {
+	var ir *IntrospectionResponse
+	json.NewDecoder(data).Decode(&ir)
+	return ir
+}
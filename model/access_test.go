@@ -23,6 +23,21 @@ func TestAccessJson(t *testing.T) {
 	}
 }
 
+func TestIntrospectionResponseJson(t *testing.T) {
+	ir := IntrospectionResponse{Active: true, ClientId: NewId(), Subject: NewId(), ExpiresAt: 1234}
+
+	json := ir.ToJson()
+	rir := IntrospectionResponseFromJson(strings.NewReader(json))
+
+	if *rir != ir {
+		t.Fatal("introspection responses didn't match")
+	}
+
+	if json := (&IntrospectionResponse{Active: false}).ToJson(); json != `{"active":false}` {
+		t.Fatal("inactive tokens shouldn't be described, got " + json)
+	}
+}
+
 func TestAccessIsValid(t *testing.T) {
 	ad := AccessData{}
 
@@ -80,6 +95,16 @@ func TestAccessIsValid(t *testing.T) {
 		t.Fatal()
 	}
 
+	ad.PreviousRefreshToken = NewRandomString(28)
+	if err := ad.IsValid(); err == nil {
+		t.Fatal("Should have failed Previous Refresh Token")
+	}
+
+	ad.PreviousRefreshToken = NewId()
+	if err := ad.IsValid(); err == nil {
+		t.Fatal()
+	}
+
 	ad.RedirectUri = ""
 	if err := ad.IsValid(); err == nil {
 		t.Fatal("Should have failed Redirect URI not set")
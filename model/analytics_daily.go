@@ -0,0 +1,44 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"net/http"
+	"time"
+)
+
+const (
+	ANALYTICS_METRIC_POSTS        = "posts"
+	ANALYTICS_METRIC_ACTIVE_USERS = "active_users"
+	ANALYTICS_METRIC_NEW_MEMBERS  = "new_members"
+
+	// ANALYTICS_DAY_FORMAT is the format of the days that metrics are rolled up for.
+	ANALYTICS_DAY_FORMAT = "2006-01-02"
+
+	ANALYTICS_SERIES_MAX_DAYS = 366
+)
+
+// AnalyticsDaily is the value of a metric over one day. Rows with an empty ChannelId are for a whole team,
+// and rows with neither a TeamId nor a ChannelId are for the whole system.
+type AnalyticsDaily struct {
+	Day       string
+	TeamId    string
+	ChannelId string
+	Metric    string
+	Value     int64
+}
+
+func IsValidAnalyticsMetric(metric string) bool {
+	return metric == ANALYTICS_METRIC_POSTS || metric == ANALYTICS_METRIC_ACTIVE_USERS || metric == ANALYTICS_METRIC_NEW_MEMBERS
+}
+
+// ParseAnalyticsDay parses a day in ANALYTICS_DAY_FORMAT in the given location.
+func ParseAnalyticsDay(day string, loc *time.Location) (time.Time, *AppError) {
+	t, err := time.ParseInLocation(ANALYTICS_DAY_FORMAT, day, loc)
+	if err != nil {
+		return time.Time{}, NewAppError("ParseAnalyticsDay", "model.analytics_daily.parse_day.app_error", map[string]interface{}{"Day": day}, err.Error(), http.StatusBadRequest)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,30 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsValidAnalyticsMetric(t *testing.T) {
+	assert.True(t, IsValidAnalyticsMetric(ANALYTICS_METRIC_POSTS))
+	assert.True(t, IsValidAnalyticsMetric(ANALYTICS_METRIC_ACTIVE_USERS))
+	assert.True(t, IsValidAnalyticsMetric(ANALYTICS_METRIC_NEW_MEMBERS))
+	assert.False(t, IsValidAnalyticsMetric(""))
+	assert.False(t, IsValidAnalyticsMetric("junk"))
+}
+
+func TestParseAnalyticsDay(t *testing.T) {
+	day, err := ParseAnalyticsDay("2018-02-28", time.UTC)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2018, time.February, 28, 0, 0, 0, 0, time.UTC), day)
+
+	for _, invalid := range []string{"", "2018-02-30", "28-02-2018", "2018-02-28T00:00:00Z"} {
+		_, err := ParseAnalyticsDay(invalid, time.UTC)
+		assert.NotNil(t, err, invalid)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+// AnalyticsUserDaily is the activity of a user over one day. It's rolled up along with the daily metrics and
+// only exists for the days on which the user did something.
+type AnalyticsUserDaily struct {
+	Day            string
+	UserId         string
+	Posts          int64
+	Replies        int64
+	Reactions      int64
+	ChannelsJoined int64
+	LastActivityAt int64
+}
+
+// AnalyticsUserActivity is the activity of a user over a period, summed up from their daily activity. Replies
+// are counted among the posts as well.
+type AnalyticsUserActivity struct {
+	UserId         string `json:"user_id"`
+	Username       string `json:"username"`
+	Posts          int64  `json:"posts"`
+	Replies        int64  `json:"replies"`
+	Reactions      int64  `json:"reactions"`
+	ChannelsJoined int64  `json:"channels_joined"`
+	LastActivityAt int64  `json:"last_activity_at"`
+}
+
+// AnalyticsUserActivityCsvHeader returns the header of a CSV file with a row for each user's activity.
+func AnalyticsUserActivityCsvHeader() []string {
+	return []string{"user_id", "username", "posts", "replies", "reactions", "channels_joined", "last_activity_at"}
+}
+
+// CsvRow returns the activity as a row under AnalyticsUserActivityCsvHeader.
+func (a *AnalyticsUserActivity) CsvRow() []string {
+	return []string{
+		a.UserId,
+		a.Username,
+		strconv.FormatInt(a.Posts, 10),
+		strconv.FormatInt(a.Replies, 10),
+		strconv.FormatInt(a.Reactions, 10),
+		strconv.FormatInt(a.ChannelsJoined, 10),
+		strconv.FormatInt(a.LastActivityAt, 10),
+	}
+}
+
+func AnalyticsUserActivityListToJson(activity []*AnalyticsUserActivity) string {
+	b, _ := json.Marshal(activity)
+	return string(b)
+}
+
+func AnalyticsUserActivityListFromJson(data io.Reader) []*AnalyticsUserActivity {
+	var activity []*AnalyticsUserActivity
+	json.NewDecoder(data).Decode(&activity)
+	return activity
+}
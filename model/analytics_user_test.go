@@ -0,0 +1,36 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyticsUserActivityCsvRow(t *testing.T) {
+	activity := &AnalyticsUserActivity{
+		UserId:         NewId(),
+		Username:       "someone",
+		Posts:          5,
+		Replies:        2,
+		Reactions:      3,
+		ChannelsJoined: 1,
+		LastActivityAt: 1234,
+	}
+
+	row := activity.CsvRow()
+	assert.Len(t, row, len(AnalyticsUserActivityCsvHeader()))
+	assert.Equal(t, []string{activity.UserId, "someone", "5", "2", "3", "1", "1234"}, row)
+}
+
+func TestAnalyticsUserActivityListJson(t *testing.T) {
+	activity := []*AnalyticsUserActivity{
+		{UserId: NewId(), Username: "someone", Posts: 5, Replies: 2},
+		{UserId: NewId(), Username: "someone_else", Reactions: 3, LastActivityAt: 1234},
+	}
+
+	assert.Equal(t, activity, AnalyticsUserActivityListFromJson(strings.NewReader(AnalyticsUserActivityListToJson(activity))))
+}
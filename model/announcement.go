@@ -0,0 +1,75 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"unicode/utf8"
+)
+
+const (
+	ANNOUNCEMENT_TEXT_MAX_RUNES = 256
+)
+
+var announcementColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{3}([0-9a-fA-F]{3})?$`)
+
+// Announcement is a system-wide banner shown to every client until it's cleared or expires. While set, it
+// takes precedence over the banner from AnnouncementSettings.
+type Announcement struct {
+	Text        string `json:"text"`
+	Color       string `json:"color"`
+	TextColor   string `json:"text_color"`
+	Dismissable bool   `json:"dismissable"`
+	ExpiresAt   int64  `json:"expires_at"`
+}
+
+func (o *Announcement) SetDefaults() {
+	if o.Color == "" {
+		o.Color = ANNOUNCEMENT_SETTINGS_DEFAULT_BANNER_COLOR
+	}
+
+	if o.TextColor == "" {
+		o.TextColor = ANNOUNCEMENT_SETTINGS_DEFAULT_BANNER_TEXT_COLOR
+	}
+}
+
+func (o *Announcement) IsValid() *AppError {
+	if o.Text == "" || utf8.RuneCountInString(o.Text) > ANNOUNCEMENT_TEXT_MAX_RUNES {
+		return NewAppError("Announcement.IsValid", "model.announcement.is_valid.text.app_error", map[string]interface{}{"MaxLength": ANNOUNCEMENT_TEXT_MAX_RUNES}, "", http.StatusBadRequest)
+	}
+
+	if !announcementColorPattern.MatchString(o.Color) {
+		return NewAppError("Announcement.IsValid", "model.announcement.is_valid.color.app_error", nil, "color="+o.Color, http.StatusBadRequest)
+	}
+
+	if !announcementColorPattern.MatchString(o.TextColor) {
+		return NewAppError("Announcement.IsValid", "model.announcement.is_valid.text_color.app_error", nil, "text_color="+o.TextColor, http.StatusBadRequest)
+	}
+
+	if o.ExpiresAt < 0 {
+		return NewAppError("Announcement.IsValid", "model.announcement.is_valid.expires_at.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+// IsExpired returns whether the announcement should no longer be shown at the given time in milliseconds.
+// Announcements without an expiry time never expire.
+func (o *Announcement) IsExpired(now int64) bool {
+	return o.ExpiresAt > 0 && now >= o.ExpiresAt
+}
+
+func (o *Announcement) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func AnnouncementFromJson(data io.Reader) *Announcement {
+	var o *Announcement
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
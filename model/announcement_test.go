@@ -0,0 +1,53 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnouncementIsValid(t *testing.T) {
+	announcement := &Announcement{Text: "Maintenance at 22:00"}
+	assert.NotNil(t, announcement.IsValid())
+
+	announcement.SetDefaults()
+	assert.Nil(t, announcement.IsValid())
+
+	announcement.Text = ""
+	assert.NotNil(t, announcement.IsValid())
+
+	announcement.Text = strings.Repeat("a", ANNOUNCEMENT_TEXT_MAX_RUNES+1)
+	assert.NotNil(t, announcement.IsValid())
+
+	announcement.Text = "Maintenance at 22:00"
+	announcement.Color = "red"
+	assert.NotNil(t, announcement.IsValid())
+
+	announcement.Color = "#f00"
+	announcement.TextColor = "#12345"
+	assert.NotNil(t, announcement.IsValid())
+
+	announcement.TextColor = "#ffffff"
+	announcement.ExpiresAt = -1
+	assert.NotNil(t, announcement.IsValid())
+}
+
+func TestAnnouncementIsExpired(t *testing.T) {
+	announcement := &Announcement{Text: "Maintenance at 22:00"}
+	assert.False(t, announcement.IsExpired(GetMillis()))
+
+	announcement.ExpiresAt = 1000
+	assert.False(t, announcement.IsExpired(999))
+	assert.True(t, announcement.IsExpired(1000))
+}
+
+func TestAnnouncementJson(t *testing.T) {
+	announcement := &Announcement{Text: "Maintenance at 22:00", Dismissable: true, ExpiresAt: 1000}
+	announcement.SetDefaults()
+
+	assert.Equal(t, announcement, AnnouncementFromJson(strings.NewReader(announcement.ToJson())))
+}
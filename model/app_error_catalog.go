@@ -0,0 +1,76 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// AppErrorCatalogEntry is an AppError that clients and integrations can rely on, with the HTTP status that it's
+// returned with and a machine code for it. Unlike its id and message, the code never changes, so it's what clients
+// should check for instead.
+type AppErrorCatalogEntry struct {
+	Id         string `json:"id"`
+	Code       string `json:"code"`
+	StatusCode int    `json:"status_code"`
+}
+
+var appErrorCatalogById map[string]*AppErrorCatalogEntry
+
+func init() {
+	appErrorCatalogById = make(map[string]*AppErrorCatalogEntry, len(appErrorCatalog))
+	for _, entry := range appErrorCatalog {
+		appErrorCatalogById[entry.Id] = entry
+	}
+}
+
+// GetAppErrorCatalog returns every registered AppError, sorted by id.
+func GetAppErrorCatalog() []*AppErrorCatalogEntry {
+	entries := make([]*AppErrorCatalogEntry, 0, len(appErrorCatalog))
+	for _, entry := range appErrorCatalog {
+		e := *entry
+		entries = append(entries, &e)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Id < entries[j].Id
+	})
+
+	return entries
+}
+
+// GetAppErrorCatalogEntry returns the registered AppError with the given id, or nil if there isn't one.
+func GetAppErrorCatalogEntry(id string) *AppErrorCatalogEntry {
+	return appErrorCatalogById[id]
+}
+
+// GetAppErrorCode returns the machine code for the AppError with the given id and status. AppErrors that haven't
+// been registered yet get a generic code for their status instead, such as "bad_request", which never contains a dot
+// like registered codes do.
+func GetAppErrorCode(id string, status int) string {
+	if entry := appErrorCatalogById[id]; entry != nil {
+		return entry.Code
+	}
+
+	if text := http.StatusText(status); text != "" {
+		return strings.Replace(strings.ToLower(text), " ", "_", -1)
+	}
+
+	return "unknown"
+}
+
+func AppErrorCatalogToJson(entries []*AppErrorCatalogEntry) string {
+	b, _ := json.Marshal(entries)
+	return string(b)
+}
+
+func AppErrorCatalogFromJson(data io.Reader) []*AppErrorCatalogEntry {
+	var entries []*AppErrorCatalogEntry
+	json.NewDecoder(data).Decode(&entries)
+	return entries
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import "net/http"
+
+// appErrorCatalog is generated by running "make app-error-catalog", which adds any AppErrors constructed in the files
+// that TestAppErrorCatalog checks that aren't registered yet. Entries can also be added by hand, but the codes of
+// existing ones must never be changed since clients depend on them.
+var appErrorCatalog = []*AppErrorCatalogEntry{
+	{Id: "api.channel.add_user_to_channel.type.app_error", Code: "channel.add_user_to_channel.type", StatusCode: http.StatusBadRequest},
+	{Id: "api.channel.set_channel_icon.no_file.app_error", Code: "channel.set_channel_icon.no_file", StatusCode: http.StatusBadRequest},
+	{Id: "api.channel.set_channel_icon.parse.app_error", Code: "channel.set_channel_icon.parse", StatusCode: http.StatusBadRequest},
+	{Id: "api.channel.set_channel_icon.too_large.app_error", Code: "channel.set_channel_icon.too_large", StatusCode: http.StatusBadRequest},
+	{Id: "api.channel.update_channel.deleted.app_error", Code: "channel.update_channel.deleted", StatusCode: http.StatusBadRequest},
+	{Id: "api.channel.update_channel.tried.app_error", Code: "channel.update_channel.tried", StatusCode: http.StatusBadRequest},
+	{Id: "api.context.api_endpoint_disabled.app_error", Code: "context.api_endpoint_disabled", StatusCode: http.StatusForbidden},
+	{Id: "api.context.database_unavailable.app_error", Code: "context.database_unavailable", StatusCode: http.StatusServiceUnavailable},
+	{Id: "api.context.invalid_body_param.app_error", Code: "context.invalid_body_param", StatusCode: http.StatusBadRequest},
+	{Id: "api.context.invalid_url_param.app_error", Code: "context.invalid_url_param", StatusCode: http.StatusBadRequest},
+	{Id: "api.context.mfa_required.app_error", Code: "context.mfa_required", StatusCode: http.StatusForbidden},
+	{Id: "api.context.permissions.app_error", Code: "context.permissions", StatusCode: http.StatusForbidden},
+	{Id: "api.context.session_expired.app_error", Code: "context.session_expired", StatusCode: http.StatusUnauthorized},
+	{Id: "api.context.token_provided.app_error", Code: "context.token_provided", StatusCode: http.StatusUnauthorized},
+	{Id: "api.user.demote_user_to_guest.disabled.app_error", Code: "user.demote_user_to_guest.disabled", StatusCode: http.StatusNotImplemented},
+	{Id: "api.user.get_profile_image.not_found.app_error", Code: "user.get_profile_image.not_found", StatusCode: http.StatusNotFound},
+	{Id: "api.user.guest_restricted.app_error", Code: "user.guest_restricted", StatusCode: http.StatusForbidden},
+	{Id: "api.user.update_active.permissions.app_error", Code: "user.update_active.permissions", StatusCode: http.StatusForbidden},
+	{Id: "api.user.update_password.context.app_error", Code: "user.update_password.context", StatusCode: http.StatusForbidden},
+	{Id: "api.user.upload_profile_user.array.app_error", Code: "user.upload_profile_user.array", StatusCode: http.StatusBadRequest},
+	{Id: "api.user.upload_profile_user.no_file.app_error", Code: "user.upload_profile_user.no_file", StatusCode: http.StatusBadRequest},
+	{Id: "api.user.upload_profile_user.parse.app_error", Code: "user.upload_profile_user.parse", StatusCode: http.StatusInternalServerError},
+	{Id: "api.user.upload_profile_user.storage.app_error", Code: "user.upload_profile_user.storage", StatusCode: http.StatusNotImplemented},
+	{Id: "api.user.upload_profile_user.too_large.app_error", Code: "user.upload_profile_user.too_large", StatusCode: http.StatusRequestEntityTooLarge},
+	{Id: "api.user.verify_email.bad_link.app_error", Code: "user.verify_email.bad_link", StatusCode: http.StatusBadRequest},
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAppErrorCode(t *testing.T) {
+	assert.Equal(t, "context.invalid_url_param", GetAppErrorCode("api.context.invalid_url_param.app_error", http.StatusBadRequest))
+	assert.Equal(t, "context.invalid_url_param", GetAppErrorCode("api.context.invalid_url_param.app_error", http.StatusInternalServerError), "registered errors should keep their code")
+	assert.Equal(t, "bad_request", GetAppErrorCode("api.not_registered.app_error", http.StatusBadRequest))
+	assert.Equal(t, "internal_server_error", GetAppErrorCode("api.not_registered.app_error", http.StatusInternalServerError))
+	assert.Equal(t, "unknown", GetAppErrorCode("api.not_registered.app_error", 0))
+}
+
+func TestAppErrorCode(t *testing.T) {
+	err := NewAppError("TestAppErrorCode", "api.context.permissions.app_error", nil, "", http.StatusForbidden)
+	assert.Equal(t, "context.permissions", err.Code)
+
+	rerr := AppErrorFromJson(strings.NewReader(err.ToJson()))
+	assert.Equal(t, err.Code, rerr.Code)
+}
+
+func TestGetAppErrorCatalog(t *testing.T) {
+	catalog := GetAppErrorCatalog()
+	require.NotEmpty(t, catalog)
+
+	for i := 1; i < len(catalog); i++ {
+		assert.True(t, catalog[i-1].Id < catalog[i].Id, "the catalog should be sorted by id")
+	}
+
+	catalog[0].Code = "changed"
+	assert.NotEqual(t, "changed", GetAppErrorCatalog()[0].Code, "the catalog shouldn't be changed through what's returned")
+
+	assert.Nil(t, GetAppErrorCatalogEntry("api.not_registered.app_error"))
+
+	rcatalog := AppErrorCatalogFromJson(strings.NewReader(AppErrorCatalogToJson(catalog)))
+	assert.Equal(t, catalog, rcatalog)
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+)
+
+const (
+	BOOT_SECTION_USER            = "user"
+	BOOT_SECTION_TEAMS           = "teams"
+	BOOT_SECTION_TEAM_MEMBERS    = "team_members"
+	BOOT_SECTION_CHANNELS        = "channels"
+	BOOT_SECTION_CHANNEL_MEMBERS = "channel_members"
+	BOOT_SECTION_PREFERENCES     = "preferences"
+	BOOT_SECTION_LICENSE         = "license"
+	BOOT_SECTION_CONFIG          = "config"
+)
+
+// BootData is everything that a client loads for the current user when it starts. Channels and ChannelMembers
+// are keyed by team id.
+type BootData struct {
+	User           *User                      `json:"user,omitempty"`
+	Teams          []*Team                    `json:"teams,omitempty"`
+	TeamMembers    []*TeamMember              `json:"team_members,omitempty"`
+	Channels       map[string]*ChannelList    `json:"channels,omitempty"`
+	ChannelMembers map[string]*ChannelMembers `json:"channel_members,omitempty"`
+	Preferences    Preferences                `json:"preferences,omitempty"`
+	License        map[string]string          `json:"license,omitempty"`
+	Config         map[string]string          `json:"config,omitempty"`
+
+	// Errors has the error for each section that couldn't be loaded. The other sections are still returned.
+	Errors map[string]*AppError `json:"errors,omitempty"`
+}
+
+func (o *BootData) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func BootDataFromJson(data io.Reader) *BootData {
+	var o *BootData
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
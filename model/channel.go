@@ -9,6 +9,7 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"regexp"
 	"sort"
 	"strings"
 	"unicode/utf8"
@@ -26,11 +27,22 @@ const (
 	CHANNEL_NAME_MIN_LENGTH        = 2
 	CHANNEL_NAME_MAX_LENGTH        = 64
 	CHANNEL_NAME_UI_MAX_LENGTH     = 22
-	CHANNEL_HEADER_MAX_RUNES       = 1024
-	CHANNEL_PURPOSE_MAX_RUNES      = 250
+	CHANNEL_HEADER_MAX_RUNES       = 4096
+	CHANNEL_PURPOSE_MAX_RUNES      = 1024
 	CHANNEL_CACHE_SIZE             = 25000
+
+	CHANNEL_JOIN_LEAVE_MESSAGES_DEFAULT = ""
+	CHANNEL_JOIN_LEAVE_MESSAGES_ON      = "on"
+	CHANNEL_JOIN_LEAVE_MESSAGES_OFF     = "off"
+
+	CHANNEL_DISABLED_COMMAND_TRIGGERS_MAX_LENGTH = 1024
+
+	CHANNEL_ICON_SIZE          = 64
+	CHANNEL_ICON_MAX_FILE_SIZE = 1024 * 1024 // 1 MB
 )
 
+var validChannelIconEmojiName = regexp.MustCompile(`^[a-zA-Z0-9\-\+_]+$`)
+
 type Channel struct {
 	Id            string `json:"id"`
 	CreateAt      int64  `json:"create_at"`
@@ -46,6 +58,46 @@ type Channel struct {
 	TotalMsgCount int64  `json:"total_msg_count"`
 	ExtraUpdateAt int64  `json:"extra_update_at"`
 	CreatorId     string `json:"creator_id"`
+	// DisableGroupMentions stops mentions of custom groups from notifying anyone in the channel.
+	DisableGroupMentions bool `json:"disable_group_mentions"`
+	// DisableChannelMentions stops @here, @channel and @all from notifying anyone in the channel.
+	DisableChannelMentions bool `json:"disable_channel_mentions"`
+	// JoinLeaveMessages controls whether system messages are posted when users join or leave the channel. It's one
+	// of the CHANNEL_JOIN_LEAVE_MESSAGES_* values, with the default deferring to the server's configuration.
+	JoinLeaveMessages string `json:"join_leave_messages"`
+	// EnableFeed makes the channel's posts available as an Atom feed to anyone with the feed's token. Only public
+	// channels can have feeds, so it's turned off whenever a channel is saved as anything else.
+	EnableFeed bool `json:"enable_feed"`
+	// FeedKey is the secret that the token for the channel's feed is generated from. It's replaced to revoke the
+	// token and is never sent to clients.
+	FeedKey string `json:"-"`
+	// DisableCustomCommands stops the slash commands that were added as integrations from running in the channel.
+	// Built-in and plugin commands still run unless their triggers are in DisabledCommandTriggers.
+	DisableCustomCommands bool `json:"disable_custom_commands"`
+	// DisabledCommandTriggers are the triggers, without the slash, of the slash commands that can't be run in the
+	// channel.
+	DisabledCommandTriggers StringArray `json:"disabled_command_triggers"`
+	// DisableReactions stops anyone, including the channel's admins, from adding reactions to its posts. Reactions
+	// that were added before can still be removed.
+	DisableReactions bool `json:"disable_reactions"`
+	// IconEmojiName is the name of the system or custom emoji that's shown beside the channel in the sidebar.
+	IconEmojiName string `json:"icon_emoji_name"`
+	// LastIconUpdate is when an icon was last uploaded for the channel, or 0 if it doesn't have one. A channel shows
+	// either an emoji or an uploaded icon, so setting one clears the other.
+	LastIconUpdate int64 `json:"last_icon_update"`
+	// MemberCount and GuestCount are the number of active users and guests in the channel. They're kept up to date
+	// by the store as members join and leave, so they're ignored when a channel is saved or updated.
+	MemberCount int64 `json:"member_count"`
+	GuestCount  int64 `json:"guest_count"`
+	// PermanentDeleteAt is when the archived channel is due to be permanently deleted, or 0 if it isn't. It's set by
+	// the store when a channel is marked for deletion, so it's ignored when a channel is saved or updated, and the
+	// channel can be restored until then.
+	PermanentDeleteAt int64 `json:"permanent_delete_at"`
+	// RedirectedFrom is set to the name that the channel was requested by when that was one of its old names.
+	RedirectedFrom string `json:"redirected_from,omitempty" db:"-"`
+	// DirectDisplayName is set on direct channels to the display name of the user on the other side of the channel to
+	// whoever requested it, so that clients don't have to look them up.
+	DirectDisplayName string `json:"direct_display_name,omitempty" db:"-"`
 }
 
 type ChannelPatch struct {
@@ -53,6 +105,20 @@ type ChannelPatch struct {
 	Name        *string `json:"name"`
 	Header      *string `json:"header"`
 	Purpose     *string `json:"purpose"`
+
+	DisableGroupMentions   *bool `json:"disable_group_mentions"`
+	DisableChannelMentions *bool `json:"disable_channel_mentions"`
+
+	JoinLeaveMessages *string `json:"join_leave_messages"`
+
+	EnableFeed *bool `json:"enable_feed"`
+
+	DisableCustomCommands   *bool        `json:"disable_custom_commands"`
+	DisabledCommandTriggers *StringArray `json:"disabled_command_triggers"`
+
+	DisableReactions *bool `json:"disable_reactions"`
+
+	IconEmojiName *string `json:"icon_emoji_name"`
 }
 
 func (o *Channel) DeepCopy() *Channel {
@@ -65,6 +131,59 @@ func (o *Channel) ToJson() string {
 	return string(b)
 }
 
+// CHANNEL_SPARSE_FIELDS are the fields of a channel that clients can ask for on their own with the fields query
+// parameter.
+var CHANNEL_SPARSE_FIELDS = map[string]bool{
+	"id": true, "create_at": true, "update_at": true, "delete_at": true, "team_id": true, "type": true,
+	"display_name": true, "name": true, "header": true, "purpose": true, "last_post_at": true,
+	"total_msg_count": true, "creator_id": true, "member_count": true, "icon_emoji_name": true,
+	"last_icon_update": true,
+}
+
+// ToSparseMap returns only the given fields of the channel for serializing. Fields that aren't in
+// CHANNEL_SPARSE_FIELDS are left out.
+func (o *Channel) ToSparseMap(fields []string) map[string]interface{} {
+	m := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		switch field {
+		case "id":
+			m[field] = o.Id
+		case "create_at":
+			m[field] = o.CreateAt
+		case "update_at":
+			m[field] = o.UpdateAt
+		case "delete_at":
+			m[field] = o.DeleteAt
+		case "team_id":
+			m[field] = o.TeamId
+		case "type":
+			m[field] = o.Type
+		case "display_name":
+			m[field] = o.DisplayName
+		case "name":
+			m[field] = o.Name
+		case "header":
+			m[field] = o.Header
+		case "purpose":
+			m[field] = o.Purpose
+		case "last_post_at":
+			m[field] = o.LastPostAt
+		case "total_msg_count":
+			m[field] = o.TotalMsgCount
+		case "creator_id":
+			m[field] = o.CreatorId
+		case "member_count":
+			m[field] = o.MemberCount
+		case "icon_emoji_name":
+			m[field] = o.IconEmojiName
+		case "last_icon_update":
+			m[field] = o.LastIconUpdate
+		}
+	}
+
+	return m
+}
+
 func (o *ChannelPatch) ToJson() string {
 	b, _ := json.Marshal(o)
 	return string(b)
@@ -82,6 +201,23 @@ func ChannelPatchFromJson(data io.Reader) *ChannelPatch {
 	return o
 }
 
+// Changes returns the fields of o that are different from old, keyed by their names in JSON, so that only those
+// need to be sent to clients that already have old.
+func (o *Channel) Changes(old *Channel) map[string]interface{} {
+	var current, previous map[string]interface{}
+	json.Unmarshal([]byte(o.ToJson()), &current)
+	json.Unmarshal([]byte(old.ToJson()), &previous)
+
+	changes := make(map[string]interface{})
+	for key, value := range current {
+		if previous[key] != value {
+			changes[key] = value
+		}
+	}
+
+	return changes
+}
+
 func (o *Channel) Etag() string {
 	return Etag(o.Id, o.UpdateAt)
 }
@@ -115,6 +251,10 @@ func (o *Channel) IsValid() *AppError {
 		return NewAppError("Channel.IsValid", "model.channel.is_valid.header.app_error", nil, "id="+o.Id, http.StatusBadRequest)
 	}
 
+	if link := FindUnsafeMarkdownLink(o.Header); link != "" {
+		return NewAppError("Channel.IsValid", "model.channel.is_valid.header_link.app_error", nil, "id="+o.Id+", link="+link, http.StatusBadRequest)
+	}
+
 	if utf8.RuneCountInString(o.Purpose) > CHANNEL_PURPOSE_MAX_RUNES {
 		return NewAppError("Channel.IsValid", "model.channel.is_valid.purpose.app_error", nil, "id="+o.Id, http.StatusBadRequest)
 	}
@@ -123,6 +263,24 @@ func (o *Channel) IsValid() *AppError {
 		return NewAppError("Channel.IsValid", "model.channel.is_valid.creator_id.app_error", nil, "", http.StatusBadRequest)
 	}
 
+	if !(o.JoinLeaveMessages == CHANNEL_JOIN_LEAVE_MESSAGES_DEFAULT || o.JoinLeaveMessages == CHANNEL_JOIN_LEAVE_MESSAGES_ON || o.JoinLeaveMessages == CHANNEL_JOIN_LEAVE_MESSAGES_OFF) {
+		return NewAppError("Channel.IsValid", "model.channel.is_valid.join_leave_messages.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	if len(ArrayToJson(o.DisabledCommandTriggers)) > CHANNEL_DISABLED_COMMAND_TRIGGERS_MAX_LENGTH {
+		return NewAppError("Channel.IsValid", "model.channel.is_valid.disabled_command_triggers.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	for _, trigger := range o.DisabledCommandTriggers {
+		if len(trigger) < MIN_TRIGGER_LENGTH || len(trigger) > MAX_TRIGGER_LENGTH || strings.HasPrefix(trigger, "/") || strings.Contains(trigger, " ") || trigger != strings.ToLower(trigger) {
+			return NewAppError("Channel.IsValid", "model.channel.is_valid.disabled_command_triggers.app_error", nil, "id="+o.Id+", trigger="+trigger, http.StatusBadRequest)
+		}
+	}
+
+	if len(o.IconEmojiName) > EMOJI_NAME_MAX_LENGTH || (o.IconEmojiName != "" && !validChannelIconEmojiName.MatchString(o.IconEmojiName)) {
+		return NewAppError("Channel.IsValid", "model.channel.is_valid.icon_emoji_name.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
 	return nil
 }
 
@@ -134,10 +292,31 @@ func (o *Channel) PreSave() {
 	o.CreateAt = GetMillis()
 	o.UpdateAt = o.CreateAt
 	o.ExtraUpdateAt = 0
+	o.MemberCount = 0
+	o.GuestCount = 0
+	o.PermanentDeleteAt = 0
+
+	o.preSaveFeed()
 }
 
 func (o *Channel) PreUpdate() {
 	o.UpdateAt = GetMillis()
+
+	o.preSaveFeed()
+}
+
+// preSaveFeed makes sure that only public channels have feeds and that the ones that do have a key to generate
+// their tokens from. Turning off a feed discards its key so that turning it back on revokes the old token.
+func (o *Channel) preSaveFeed() {
+	if o.Type != CHANNEL_OPEN {
+		o.EnableFeed = false
+	}
+
+	if !o.EnableFeed {
+		o.FeedKey = ""
+	} else if o.FeedKey == "" {
+		o.FeedKey = NewId()
+	}
 }
 
 func (o *Channel) IsGroupOrDirect() bool {
@@ -160,6 +339,74 @@ func (o *Channel) Patch(patch *ChannelPatch) {
 	if patch.Purpose != nil {
 		o.Purpose = *patch.Purpose
 	}
+
+	if patch.DisableGroupMentions != nil {
+		o.DisableGroupMentions = *patch.DisableGroupMentions
+	}
+
+	if patch.DisableChannelMentions != nil {
+		o.DisableChannelMentions = *patch.DisableChannelMentions
+	}
+
+	if patch.JoinLeaveMessages != nil {
+		o.JoinLeaveMessages = *patch.JoinLeaveMessages
+	}
+
+	if patch.EnableFeed != nil {
+		o.EnableFeed = *patch.EnableFeed
+	}
+
+	if patch.DisableCustomCommands != nil {
+		o.DisableCustomCommands = *patch.DisableCustomCommands
+	}
+
+	if patch.DisabledCommandTriggers != nil {
+		o.DisabledCommandTriggers = *patch.DisabledCommandTriggers
+	}
+
+	if patch.DisableReactions != nil {
+		o.DisableReactions = *patch.DisableReactions
+	}
+
+	if patch.IconEmojiName != nil {
+		o.IconEmojiName = *patch.IconEmojiName
+		if o.IconEmojiName != "" {
+			o.LastIconUpdate = 0
+		}
+	}
+}
+
+// IsCommandTriggerDisabled returns true if the slash command with the given trigger can't be run in the channel.
+func (o *Channel) IsCommandTriggerDisabled(trigger string) bool {
+	for _, disabled := range o.DisabledCommandTriggers {
+		if disabled == trigger {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetOtherUserIdForDM returns the id of the user on the other side of a direct channel from userId, which is userId
+// itself for a direct channel with oneself. An empty string is returned if the channel isn't a direct channel or
+// userId isn't in it.
+func (o *Channel) GetOtherUserIdForDM(userId string) string {
+	if o.Type != CHANNEL_DIRECT {
+		return ""
+	}
+
+	userIds := strings.Split(o.Name, "__")
+	if len(userIds) != 2 {
+		return ""
+	}
+
+	if userIds[0] == userId {
+		return userIds[1]
+	} else if userIds[1] == userId {
+		return userIds[0]
+	}
+
+	return ""
 }
 
 func GetDMNameFromIds(userId1, userId2 string) string {
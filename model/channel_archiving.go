@@ -0,0 +1,25 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+// CHANNEL_ARCHIVING_KEEP_HASHTAG exempts a channel from being archived for being inactive when it's in one of the
+// channel's pinned posts.
+const CHANNEL_ARCHIVING_KEEP_HASHTAG = "#keep"
+
+// ChannelActivity is when a channel last had a post that wasn't a system message, or when it was created if it's
+// never had one, and when its members were last warned that it would be archived for being inactive.
+type ChannelActivity struct {
+	ChannelId      string
+	LastActivityAt int64
+	WarnedAt       int64
+}
+
+// ChannelArchivingResult is what a run of inactive channel archiving did, or would have done if it was a dry run.
+type ChannelArchivingResult struct {
+	DryRun             bool     `json:"dry_run"`
+	InactiveSince      int64    `json:"inactive_since"`
+	WarnedChannelIds   []string `json:"warned_channel_ids"`
+	ArchivedChannelIds []string `json:"archived_channel_ids"`
+	ExemptCount        int      `json:"exempt_count"`
+}
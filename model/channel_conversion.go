@@ -0,0 +1,39 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// ChannelIntegrations lists the integrations that can read from or post to a channel, so that admins can review
+// what's exposed to them after the channel is made public or private.
+type ChannelIntegrations struct {
+	IncomingWebhooks []*IncomingWebhook `json:"incoming_webhooks"`
+	// OutgoingWebhooks includes the team's webhooks that aren't limited to a channel, since they're triggered by
+	// posts in any of its public channels.
+	OutgoingWebhooks []*OutgoingWebhook `json:"outgoing_webhooks"`
+	// Commands are the team's custom slash commands, which can be used from any of its channels.
+	Commands []*Command `json:"commands"`
+	Bots     []*User    `json:"bots"`
+}
+
+// ChannelConversion is the result of converting a channel between public and private. It's serialized as the
+// channel with the integrations added alongside its other fields.
+type ChannelConversion struct {
+	*Channel
+	Integrations *ChannelIntegrations `json:"integrations"`
+}
+
+func (o *ChannelConversion) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func ChannelConversionFromJson(data io.Reader) *ChannelConversion {
+	var o *ChannelConversion
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
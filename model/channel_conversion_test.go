@@ -0,0 +1,42 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelConversionJson(t *testing.T) {
+	conversion := &ChannelConversion{
+		Channel: &Channel{Id: NewId(), Name: "name", Type: CHANNEL_OPEN},
+		Integrations: &ChannelIntegrations{
+			IncomingWebhooks: []*IncomingWebhook{{Id: NewId()}},
+			OutgoingWebhooks: []*OutgoingWebhook{},
+			Commands:         []*Command{{Id: NewId(), Trigger: "trigger"}},
+			Bots:             []*User{{Id: NewId(), IsBot: true}},
+		},
+	}
+
+	json := conversion.ToJson()
+
+	// Clients that expect a channel can still read the response
+	channel := ChannelFromJson(strings.NewReader(json))
+	require.NotNil(t, channel)
+	assert.Equal(t, conversion.Id, channel.Id)
+	assert.Equal(t, conversion.Type, channel.Type)
+
+	result := ChannelConversionFromJson(strings.NewReader(json))
+	require.NotNil(t, result)
+	require.NotNil(t, result.Channel)
+	assert.Equal(t, conversion.Id, result.Id)
+	require.NotNil(t, result.Integrations)
+	assert.Equal(t, conversion.Integrations.IncomingWebhooks[0].Id, result.Integrations.IncomingWebhooks[0].Id)
+	assert.Empty(t, result.Integrations.OutgoingWebhooks)
+	assert.Equal(t, "trigger", result.Integrations.Commands[0].Trigger)
+	assert.True(t, result.Integrations.Bots[0].IsBot)
+}
@@ -52,3 +52,12 @@ func ChannelCountsFromJson(data io.Reader) *ChannelCounts {
 	json.NewDecoder(data).Decode(&o)
 	return o
 }
+
+// ChannelMemberCountsBatch is the result of checking the member and guest counts of a batch of channels against
+// their members.
+type ChannelMemberCountsBatch struct {
+	// LastChannelId is the id of the last channel in the batch, or empty if there were no channels left to check.
+	LastChannelId string
+	// Corrected is the number of channels in the batch whose counts had drifted and were fixed.
+	Corrected int64
+}
@@ -0,0 +1,14 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+// ChannelDeletionResult is what a run of the job that permanently deletes the channels marked for deletion has done
+// so far.
+type ChannelDeletionResult struct {
+	ChannelsDue     int   `json:"channels_due"`
+	ChannelsDeleted int   `json:"channels_deleted"`
+	FilesDeleted    int64 `json:"files_deleted"`
+	// ChannelId is the channel that's being deleted, or the one that failed to be.
+	ChannelId string `json:"channel_id"`
+}
@@ -0,0 +1,33 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+)
+
+const (
+	CHANNEL_FEED_MAX_POST_COUNT = 200
+	CHANNEL_FEED_CONTENT_TYPE   = "application/atom+xml; charset=utf-8"
+)
+
+// ChannelFeed is the address of a channel's Atom feed. Anyone with the token in it can read the feed, so it's only
+// given to those who can manage the channel.
+type ChannelFeed struct {
+	ChannelId string `json:"channel_id"`
+	Token     string `json:"token"`
+	Url       string `json:"url"`
+}
+
+func (o *ChannelFeed) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func ChannelFeedFromJson(data io.Reader) *ChannelFeed {
+	var o *ChannelFeed
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
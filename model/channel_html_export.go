@@ -0,0 +1,55 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"time"
+)
+
+const (
+	CHANNEL_HTML_EXPORT_INDEX_FILE   = "index.html"
+	CHANNEL_HTML_EXPORT_FILES_DIR    = "files"
+	CHANNEL_HTML_EXPORT_EMOJI_DIR    = "emoji"
+	CHANNEL_HTML_EXPORT_MONTH_FORMAT = "2006-01"
+)
+
+// ChannelHtmlExport describes a channel that was exported as a static HTML archive, with a page for each month
+// that has posts in it.
+type ChannelHtmlExport struct {
+	ChannelId        string
+	PostCount        int
+	FileCount        int
+	MissingFileCount int
+	Months           []*ChannelHtmlExportMonth
+	Participants     []*ChannelHtmlExportParticipant
+}
+
+type ChannelHtmlExportMonth struct {
+	Month     string
+	FileName  string
+	PostCount int
+}
+
+// ChannelHtmlExportParticipant is someone who made posts in an exported channel. Deactivated users keep the
+// username that they had when they were deactivated.
+type ChannelHtmlExportParticipant struct {
+	UserId        string
+	Username      string
+	FullName      string
+	IsDeactivated bool
+	PostCount     int
+	FirstPostAt   int64
+	LastPostAt    int64
+}
+
+// ChannelHtmlExportMonthName returns the name of the month that a post made at millis belongs to in an export,
+// such as 2018-01.
+func ChannelHtmlExportMonthName(millis int64, location *time.Location) string {
+	return time.Unix(0, millis*int64(time.Millisecond)).In(location).Format(CHANNEL_HTML_EXPORT_MONTH_FORMAT)
+}
+
+// ChannelHtmlExportMonthFileName returns the name of the page of an export that has the posts from a month.
+func ChannelHtmlExportMonthFileName(month string) string {
+	return month + ".html"
+}
@@ -0,0 +1,23 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelHtmlExportMonthName(t *testing.T) {
+	millis := time.Date(2018, time.January, 31, 23, 30, 0, 0, time.UTC).UnixNano() / int64(time.Millisecond)
+	assert.Equal(t, "2018-01", ChannelHtmlExportMonthName(millis, time.UTC))
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	assert.Equal(t, "2018-02", ChannelHtmlExportMonthName(millis, tokyo), "months should be split in the given timezone")
+
+	assert.Equal(t, "2018-02.html", ChannelHtmlExportMonthFileName("2018-02"))
+}
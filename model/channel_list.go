@@ -18,6 +18,24 @@ func (o *ChannelList) ToJson() string {
 	}
 }
 
+// ToSparseJson serializes only the given fields of each channel, or all of them if no fields are given.
+func (o *ChannelList) ToSparseJson(fields []string) string {
+	if len(fields) == 0 {
+		return o.ToJson()
+	}
+
+	sparse := make([]map[string]interface{}, len(*o))
+	for i, channel := range *o {
+		sparse[i] = channel.ToSparseMap(fields)
+	}
+
+	if b, err := json.Marshal(sparse); err != nil {
+		return "[]"
+	} else {
+		return string(b)
+	}
+}
+
 func (o *ChannelList) Etag() string {
 
 	id := "0"
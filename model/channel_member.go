@@ -17,14 +17,19 @@ const (
 	CHANNEL_NOTIFY_NONE         = "none"
 	CHANNEL_MARK_UNREAD_ALL     = "all"
 	CHANNEL_MARK_UNREAD_MENTION = "mention"
+
+	// CHANNEL_NOTIFY_PROPS_BULK_MAX_CHANNELS is how many of a user's channels can have their notify props changed at once.
+	CHANNEL_NOTIFY_PROPS_BULK_MAX_CHANNELS = 500
 )
 
 type ChannelUnread struct {
-	TeamId       string    `json:"team_id"`
-	ChannelId    string    `json:"channel_id"`
-	MsgCount     int64     `json:"msg_count"`
-	MentionCount int64     `json:"mention_count"`
-	NotifyProps  StringMap `json:"-"`
+	TeamId         string    `json:"team_id"`
+	ChannelId      string    `json:"channel_id"`
+	MsgCount       int64     `json:"msg_count"`
+	MentionCount   int64     `json:"mention_count"`
+	LastViewedAt   int64     `json:"last_viewed_at"`
+	LastReadPostId string    `json:"last_read_post_id"`
+	NotifyProps    StringMap `json:"-"`
 }
 
 type ChannelMember struct {
@@ -36,10 +41,26 @@ type ChannelMember struct {
 	MentionCount int64     `json:"mention_count"`
 	NotifyProps  StringMap `json:"notify_props"`
 	LastUpdateAt int64     `json:"last_update_at"`
+
+	// LastReadPostId is the last post that the user has acknowledged reading, which clients place the new messages
+	// line after. Unlike LastViewedAt, it's only moved by the user reading or marking posts as unread, and never
+	// backwards by reading an earlier post. LastReadPostAt is when that post was created.
+	LastReadPostId string `json:"last_read_post_id"`
+	LastReadPostAt int64  `json:"last_read_post_at"`
 }
 
 type ChannelMembers []ChannelMember
 
+// ChannelNotifyPropsByChannel is the notify props to change for a user's membership of each of several channels, keyed
+// by channel id.
+type ChannelNotifyPropsByChannel map[string]StringMap
+
+// ChannelMembersRoles gives several members of a channel the same roles at once.
+type ChannelMembersRoles struct {
+	UserIds []string `json:"user_ids"`
+	Roles   string   `json:"roles"`
+}
+
 func (o *ChannelMembers) ToJson() string {
 	if b, err := json.Marshal(o); err != nil {
 		return "[]"
@@ -65,6 +86,20 @@ func ChannelUnreadFromJson(data io.Reader) *ChannelUnread {
 	return o
 }
 
+func ChannelUnreadsToJson(o []*ChannelUnread) string {
+	if b, err := json.Marshal(o); err != nil {
+		return "[]"
+	} else {
+		return string(b)
+	}
+}
+
+func ChannelUnreadsFromJson(data io.Reader) []*ChannelUnread {
+	var o []*ChannelUnread
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
+
 func (o *ChannelMember) ToJson() string {
 	b, _ := json.Marshal(o)
 	return string(b)
@@ -76,6 +111,28 @@ func ChannelMemberFromJson(data io.Reader) *ChannelMember {
 	return o
 }
 
+func (o ChannelNotifyPropsByChannel) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func ChannelNotifyPropsByChannelFromJson(data io.Reader) ChannelNotifyPropsByChannel {
+	var o ChannelNotifyPropsByChannel
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
+
+func (o *ChannelMembersRoles) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func ChannelMembersRolesFromJson(data io.Reader) *ChannelMembersRoles {
+	var o *ChannelMembersRoles
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
+
 func (o *ChannelMember) IsValid() *AppError {
 
 	if len(o.ChannelId) != 26 {
@@ -138,6 +195,30 @@ func IsSendEmailValid(sendEmail string) bool {
 	return sendEmail == CHANNEL_NOTIFY_DEFAULT || sendEmail == "true" || sendEmail == "false"
 }
 
+// IsChannelNotifyPropsValid returns an error if any of the props isn't one that channel members have or is set to a
+// value that it can't have. Unlike ChannelMember.IsValid, it doesn't require any of them to be given.
+func IsChannelNotifyPropsValid(props map[string]string) *AppError {
+	for name, value := range props {
+		var valid bool
+		switch name {
+		case DESKTOP_NOTIFY_PROP, PUSH_NOTIFY_PROP:
+			valid = IsChannelNotifyLevelValid(value)
+		case MARK_UNREAD_NOTIFY_PROP:
+			valid = IsChannelMarkUnreadLevelValid(value)
+		case EMAIL_NOTIFY_PROP:
+			valid = IsSendEmailValid(value)
+		default:
+			return NewAppError("IsChannelNotifyPropsValid", "model.channel_member.notify_props.unknown.app_error", map[string]interface{}{"Name": name}, "name="+name, http.StatusBadRequest)
+		}
+
+		if !valid {
+			return NewAppError("IsChannelNotifyPropsValid", "model.channel_member.notify_props.invalid_value.app_error", map[string]interface{}{"Name": name, "Value": value}, "name="+name+", value="+value, http.StatusBadRequest)
+		}
+	}
+
+	return nil
+}
+
 func GetDefaultChannelNotifyProps() StringMap {
 	return StringMap{
 		DESKTOP_NOTIFY_PROP:     CHANNEL_NOTIFY_DEFAULT,
@@ -3,9 +3,23 @@
 
 package model
 
+const (
+	// CHANNEL_MEMBER_HISTORY_ACTOR_SYSTEM is recorded as the actor of membership changes that weren't made by a
+	// user, such as those made from the CLI or by an import.
+	CHANNEL_MEMBER_HISTORY_ACTOR_SYSTEM = "system"
+
+	CHANNEL_MEMBER_LEAVE_REASON_LEFT    = "left"
+	CHANNEL_MEMBER_LEAVE_REASON_REMOVED = "removed"
+	// the user left or was removed from the team the channel is in
+	CHANNEL_MEMBER_LEAVE_REASON_LEFT_TEAM = "left_team"
+)
+
 type ChannelMemberHistory struct {
-	ChannelId string
-	UserId    string
-	JoinTime  int64
-	LeaveTime *int64
+	ChannelId    string
+	UserId       string
+	JoinTime     int64
+	LeaveTime    *int64
+	JoinActorId  string
+	LeaveActorId string
+	LeaveReason  string
 }
@@ -3,13 +3,32 @@
 
 package model
 
+import (
+	"encoding/json"
+	"io"
+)
+
 type ChannelMemberHistoryResult struct {
-	ChannelId string
-	UserId    string
-	JoinTime  int64
-	LeaveTime *int64
+	ChannelId    string `json:"channel_id"`
+	UserId       string `json:"user_id"`
+	JoinTime     int64  `json:"join_time"`
+	LeaveTime    *int64 `json:"leave_time,omitempty"`
+	JoinActorId  string `json:"join_actor_id"`
+	LeaveActorId string `json:"leave_actor_id,omitempty"`
+	LeaveReason  string `json:"leave_reason,omitempty"`
 
 	// these two fields are never set in the database - when we SELECT, we join on Users to get them
-	UserEmail string `db:"Email"`
-	Username  string
+	UserEmail string `db:"Email" json:"user_email"`
+	Username  string `json:"username"`
+}
+
+func ChannelMemberHistoryResultListToJson(l []*ChannelMemberHistoryResult) string {
+	b, _ := json.Marshal(l)
+	return string(b)
+}
+
+func ChannelMemberHistoryResultListFromJson(data io.Reader) []*ChannelMemberHistoryResult {
+	var l []*ChannelMemberHistoryResult
+	json.NewDecoder(data).Decode(&l)
+	return l
 }
@@ -83,3 +83,25 @@ func TestChannelUnreadJson(t *testing.T) {
 		t.Fatal("MentionCount do not match")
 	}
 }
+
+func TestIsChannelNotifyPropsValid(t *testing.T) {
+	if err := IsChannelNotifyPropsValid(map[string]string{}); err != nil {
+		t.Fatal("no props should be valid")
+	}
+
+	if err := IsChannelNotifyPropsValid(map[string]string{DESKTOP_NOTIFY_PROP: CHANNEL_NOTIFY_MENTION, EMAIL_NOTIFY_PROP: "false"}); err != nil {
+		t.Fatal("should be valid", err)
+	}
+
+	if err := IsChannelNotifyPropsValid(map[string]string{PUSH_NOTIFY_PROP: "sometimes"}); err == nil || err.Id != "model.channel_member.notify_props.invalid_value.app_error" {
+		t.Fatal("should be an invalid value", err)
+	}
+
+	if err := IsChannelNotifyPropsValid(map[string]string{MARK_UNREAD_NOTIFY_PROP: CHANNEL_NOTIFY_NONE}); err == nil || err.Id != "model.channel_member.notify_props.invalid_value.app_error" {
+		t.Fatal("should be an invalid value", err)
+	}
+
+	if err := IsChannelNotifyPropsValid(map[string]string{"volume": "loud"}); err == nil || err.Id != "model.channel_member.notify_props.unknown.app_error" {
+		t.Fatal("should be an unknown prop", err)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+)
+
+const (
+	CHANNEL_MODERATED_PERMISSION_CREATE_POSTS         = "create_posts"
+	CHANNEL_MODERATED_PERMISSION_CREATE_REACTIONS     = "create_reactions"
+	CHANNEL_MODERATED_PERMISSION_MANAGE_MEMBERS       = "manage_members"
+	CHANNEL_MODERATED_PERMISSION_USE_CHANNEL_MENTIONS = "use_channel_mentions"
+)
+
+var CHANNEL_MODERATED_PERMISSIONS = []string{
+	CHANNEL_MODERATED_PERMISSION_CREATE_POSTS,
+	CHANNEL_MODERATED_PERMISSION_CREATE_REACTIONS,
+	CHANNEL_MODERATED_PERMISSION_MANAGE_MEMBERS,
+	CHANNEL_MODERATED_PERMISSION_USE_CHANNEL_MENTIONS,
+}
+
+// ChannelModeratedRole is the state of a moderated permission for one class of users. Value is
+// whether those users have the permission and Enabled is whether it can be changed.
+type ChannelModeratedRole struct {
+	Value   bool `json:"value"`
+	Enabled bool `json:"enabled"`
+}
+
+type ChannelModeratedRoles struct {
+	Guests  *ChannelModeratedRole `json:"guests"`
+	Members *ChannelModeratedRole `json:"members"`
+}
+
+// ChannelModeration describes which users of a channel are allowed to do a moderated action.
+type ChannelModeration struct {
+	Name  string                 `json:"name"`
+	Roles *ChannelModeratedRoles `json:"roles"`
+}
+
+type ChannelModeratedRolesPatch struct {
+	Guests  *bool `json:"guests"`
+	Members *bool `json:"members"`
+}
+
+type ChannelModerationPatch struct {
+	Name  *string                     `json:"name"`
+	Roles *ChannelModeratedRolesPatch `json:"roles"`
+}
+
+// ChannelModeratedPermissions lists the moderated permissions that have been taken away from the guests and from the
+// regular members of a channel.
+type ChannelModeratedPermissions struct {
+	Guests  []string `json:"guests"`
+	Members []string `json:"members"`
+}
+
+// IsModerated returns true if the named moderated permission has been taken away from the channel's guests, if
+// guests is true, or from its regular members otherwise.
+func (p *ChannelModeratedPermissions) IsModerated(name string, guests bool) bool {
+	moderated := p.Members
+	if guests {
+		moderated = p.Guests
+	}
+
+	for _, moderatedName := range moderated {
+		if moderatedName == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsValidChannelModeratedPermission returns true if name is one of the channel moderation settings.
+func IsValidChannelModeratedPermission(name string) bool {
+	for _, moderated := range CHANNEL_MODERATED_PERMISSIONS {
+		if name == moderated {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ChannelModeratedPermissionFor returns the name of the channel moderation setting that controls
+// the given permission, or an empty string if the permission can't be moderated.
+func ChannelModeratedPermissionFor(permissionId string) string {
+	switch permissionId {
+	case PERMISSION_CREATE_POST.Id:
+		return CHANNEL_MODERATED_PERMISSION_CREATE_POSTS
+	case PERMISSION_ADD_REACTION.Id, PERMISSION_REMOVE_REACTION.Id:
+		return CHANNEL_MODERATED_PERMISSION_CREATE_REACTIONS
+	case PERMISSION_MANAGE_PUBLIC_CHANNEL_MEMBERS.Id, PERMISSION_MANAGE_PRIVATE_CHANNEL_MEMBERS.Id:
+		return CHANNEL_MODERATED_PERMISSION_MANAGE_MEMBERS
+	}
+
+	return ""
+}
+
+func ChannelModerationsToJson(moderations []*ChannelModeration) string {
+	b, _ := json.Marshal(moderations)
+	return string(b)
+}
+
+func ChannelModerationsFromJson(data io.Reader) []*ChannelModeration {
+	var o []*ChannelModeration
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
+
+func ChannelModerationsPatchToJson(patches []*ChannelModerationPatch) string {
+	b, _ := json.Marshal(patches)
+	return string(b)
+}
+
+func ChannelModerationsPatchFromJson(data io.Reader) []*ChannelModerationPatch {
+	var o []*ChannelModerationPatch
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelModeratedPermissionFor(t *testing.T) {
+	assert.Equal(t, CHANNEL_MODERATED_PERMISSION_CREATE_POSTS, ChannelModeratedPermissionFor(PERMISSION_CREATE_POST.Id))
+	assert.Equal(t, CHANNEL_MODERATED_PERMISSION_CREATE_REACTIONS, ChannelModeratedPermissionFor(PERMISSION_ADD_REACTION.Id))
+	assert.Equal(t, CHANNEL_MODERATED_PERMISSION_CREATE_REACTIONS, ChannelModeratedPermissionFor(PERMISSION_REMOVE_REACTION.Id))
+	assert.Equal(t, CHANNEL_MODERATED_PERMISSION_MANAGE_MEMBERS, ChannelModeratedPermissionFor(PERMISSION_MANAGE_PUBLIC_CHANNEL_MEMBERS.Id))
+	assert.Equal(t, CHANNEL_MODERATED_PERMISSION_MANAGE_MEMBERS, ChannelModeratedPermissionFor(PERMISSION_MANAGE_PRIVATE_CHANNEL_MEMBERS.Id))
+	assert.Equal(t, "", ChannelModeratedPermissionFor(PERMISSION_READ_CHANNEL.Id))
+	assert.Equal(t, "", ChannelModeratedPermissionFor(PERMISSION_MANAGE_CHANNEL_ROLES.Id))
+
+	for _, name := range CHANNEL_MODERATED_PERMISSIONS {
+		assert.True(t, IsValidChannelModeratedPermission(name))
+	}
+	assert.False(t, IsValidChannelModeratedPermission("junk"))
+}
+
+func TestChannelModerationsPatchJson(t *testing.T) {
+	name := CHANNEL_MODERATED_PERMISSION_CREATE_POSTS
+	members := false
+	patches := []*ChannelModerationPatch{
+		{Name: &name, Roles: &ChannelModeratedRolesPatch{Members: &members}},
+	}
+
+	decoded := ChannelModerationsPatchFromJson(strings.NewReader(ChannelModerationsPatchToJson(patches)))
+	require.Len(t, decoded, 1)
+	assert.Equal(t, name, *decoded[0].Name)
+	assert.False(t, *decoded[0].Roles.Members)
+	assert.Nil(t, decoded[0].Roles.Guests)
+
+	assert.Nil(t, ChannelModerationsPatchFromJson(strings.NewReader("junk")))
+}
+
+func TestChannelModeratedPermissionsIsModerated(t *testing.T) {
+	moderated := &ChannelModeratedPermissions{
+		Guests:  []string{CHANNEL_MODERATED_PERMISSION_CREATE_REACTIONS},
+		Members: []string{CHANNEL_MODERATED_PERMISSION_CREATE_POSTS},
+	}
+
+	assert.True(t, moderated.IsModerated(CHANNEL_MODERATED_PERMISSION_CREATE_POSTS, false))
+	assert.False(t, moderated.IsModerated(CHANNEL_MODERATED_PERMISSION_CREATE_POSTS, true))
+	assert.True(t, moderated.IsModerated(CHANNEL_MODERATED_PERMISSION_CREATE_REACTIONS, true))
+	assert.False(t, moderated.IsModerated(CHANNEL_MODERATED_PERMISSION_CREATE_REACTIONS, false))
+	assert.False(t, (&ChannelModeratedPermissions{}).IsModerated(CHANNEL_MODERATED_PERMISSION_CREATE_POSTS, false))
+}
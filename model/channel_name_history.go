@@ -0,0 +1,60 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// CHANNEL_NAME_HISTORY_MAX_PER_CHANNEL is how many of a channel's old names are kept. The oldest ones are forgotten
+// once there are more than this.
+const CHANNEL_NAME_HISTORY_MAX_PER_CHANNEL = 10
+
+// ChannelNameHistory records that a channel used to be called Name, so that links to it by that name still work
+// after it's been renamed. Each old name can belong to at most one channel in a team.
+type ChannelNameHistory struct {
+	TeamId    string `json:"team_id"`
+	Name      string `json:"name"`
+	ChannelId string `json:"channel_id"`
+	CreateAt  int64  `json:"create_at"`
+}
+
+func (o *ChannelNameHistory) PreSave() {
+	if o.CreateAt == 0 {
+		o.CreateAt = GetMillis()
+	}
+}
+
+func (o *ChannelNameHistory) IsValid() *AppError {
+	if len(o.TeamId) != 26 {
+		return NewAppError("ChannelNameHistory.IsValid", "model.channel_name_history.is_valid.team_id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if !IsValidChannelIdentifier(o.Name) {
+		return NewAppError("ChannelNameHistory.IsValid", "model.channel_name_history.is_valid.name.app_error", nil, "channel_id="+o.ChannelId, http.StatusBadRequest)
+	}
+
+	if len(o.ChannelId) != 26 {
+		return NewAppError("ChannelNameHistory.IsValid", "model.channel_name_history.is_valid.channel_id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if o.CreateAt == 0 {
+		return NewAppError("ChannelNameHistory.IsValid", "model.channel_name_history.is_valid.create_at.app_error", nil, "channel_id="+o.ChannelId, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func ChannelNameHistoryListToJson(history []*ChannelNameHistory) string {
+	b, _ := json.Marshal(history)
+	return string(b)
+}
+
+func ChannelNameHistoryListFromJson(data io.Reader) []*ChannelNameHistory {
+	var o []*ChannelNameHistory
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelNameHistoryIsValid(t *testing.T) {
+	history := &ChannelNameHistory{
+		TeamId:    NewId(),
+		Name:      "old-name",
+		ChannelId: NewId(),
+	}
+	require.NotNil(t, history.IsValid())
+
+	history.PreSave()
+	require.Nil(t, history.IsValid())
+
+	history.Name = "Old Name"
+	require.NotNil(t, history.IsValid())
+	history.Name = "old-name"
+
+	history.TeamId = ""
+	require.NotNil(t, history.IsValid())
+	history.TeamId = NewId()
+
+	history.ChannelId = "junk"
+	require.NotNil(t, history.IsValid())
+}
+
+func TestChannelNameHistoryListJson(t *testing.T) {
+	history := []*ChannelNameHistory{{TeamId: NewId(), Name: "old-name", ChannelId: NewId(), CreateAt: 1}}
+
+	assert.Equal(t, history, ChannelNameHistoryListFromJson(strings.NewReader(ChannelNameHistoryListToJson(history))))
+}
+
+func TestChannelRedirectedFromJson(t *testing.T) {
+	channel := &Channel{Id: NewId(), Name: "new-name"}
+	assert.NotContains(t, channel.ToJson(), "redirected_from")
+
+	channel.RedirectedFrom = "old-name"
+	assert.Equal(t, "old-name", ChannelFromJson(strings.NewReader(channel.ToJson())).RedirectedFrom)
+}
@@ -11,6 +11,7 @@ import (
 type ChannelStats struct {
 	ChannelId   string `json:"channel_id"`
 	MemberCount int64  `json:"member_count"`
+	GuestCount  int64  `json:"guest_count"`
 }
 
 func (o *ChannelStats) ToJson() string {
@@ -6,6 +6,9 @@ package model
 import (
 	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestChannelJson(t *testing.T) {
@@ -37,11 +40,14 @@ func TestChannelCopy(t *testing.T) {
 }
 
 func TestChannelPatch(t *testing.T) {
-	p := &ChannelPatch{Name: new(string), DisplayName: new(string), Header: new(string), Purpose: new(string)}
+	p := &ChannelPatch{Name: new(string), DisplayName: new(string), Header: new(string), Purpose: new(string), DisableGroupMentions: new(bool), DisableChannelMentions: new(bool), JoinLeaveMessages: new(string)}
 	*p.Name = NewId()
 	*p.DisplayName = NewId()
 	*p.Header = NewId()
 	*p.Purpose = NewId()
+	*p.DisableGroupMentions = true
+	*p.DisableChannelMentions = true
+	*p.JoinLeaveMessages = CHANNEL_JOIN_LEAVE_MESSAGES_OFF
 
 	o := Channel{Id: NewId(), Name: NewId()}
 	o.Patch(p)
@@ -58,6 +64,32 @@ func TestChannelPatch(t *testing.T) {
 	if *p.Purpose != o.Purpose {
 		t.Fatal("do not match")
 	}
+	if *p.DisableGroupMentions != o.DisableGroupMentions {
+		t.Fatal("do not match")
+	}
+	if *p.DisableChannelMentions != o.DisableChannelMentions {
+		t.Fatal("do not match")
+	}
+	if *p.JoinLeaveMessages != o.JoinLeaveMessages {
+		t.Fatal("do not match")
+	}
+
+	o.Patch(&ChannelPatch{EnableFeed: NewBool(true)})
+	if !o.EnableFeed {
+		t.Fatal("feed should be enabled")
+	}
+
+	o.Patch(&ChannelPatch{DisableCustomCommands: NewBool(true), DisabledCommandTriggers: &StringArray{"echo"}})
+	assert.True(t, o.DisableCustomCommands)
+	assert.Equal(t, StringArray{"echo"}, o.DisabledCommandTriggers)
+
+	o.Patch(&ChannelPatch{DisableReactions: NewBool(true)})
+	assert.True(t, o.DisableReactions)
+
+	o.LastIconUpdate = GetMillis()
+	o.Patch(&ChannelPatch{IconEmojiName: NewString("smile")})
+	assert.Equal(t, "smile", o.IconEmojiName)
+	assert.Zero(t, o.LastIconUpdate, "setting an emoji should clear the uploaded icon")
 }
 
 func TestChannelIsValid(t *testing.T) {
@@ -110,7 +142,7 @@ func TestChannelIsValid(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	o.Header = strings.Repeat("01234567890", 100)
+	o.Header = strings.Repeat("01234567890", 373)
 	if err := o.IsValid(); err == nil {
 		t.Fatal("should be invalid")
 	}
@@ -120,7 +152,7 @@ func TestChannelIsValid(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	o.Purpose = strings.Repeat("01234567890", 30)
+	o.Purpose = strings.Repeat("01234567890", 94)
 	if err := o.IsValid(); err == nil {
 		t.Fatal("should be invalid")
 	}
@@ -130,10 +162,44 @@ func TestChannelIsValid(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	o.Purpose = strings.Repeat("0123456789", 25)
+	o.Purpose = strings.Repeat("0123456789", 102)
+	if err := o.IsValid(); err != nil {
+		t.Fatal(err)
+	}
+
+	o.JoinLeaveMessages = "sometimes"
+	if err := o.IsValid(); err == nil {
+		t.Fatal("should be invalid")
+	}
+
+	o.JoinLeaveMessages = CHANNEL_JOIN_LEAVE_MESSAGES_OFF
+	if err := o.IsValid(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, trigger := range []string{"", "/echo", "two words", "Echo", strings.Repeat("a", MAX_TRIGGER_LENGTH+1)} {
+		o.DisabledCommandTriggers = StringArray{trigger}
+		if err := o.IsValid(); err == nil {
+			t.Fatalf("should be invalid with trigger %q", trigger)
+		}
+	}
+
+	o.DisabledCommandTriggers = StringArray{"echo", "giphy"}
 	if err := o.IsValid(); err != nil {
 		t.Fatal(err)
 	}
+	assert.True(t, o.IsCommandTriggerDisabled("echo"))
+	assert.False(t, o.IsCommandTriggerDisabled("shrug"))
+
+	for _, name := range []string{"smi le", ":smile:", strings.Repeat("a", EMOJI_NAME_MAX_LENGTH+1)} {
+		o.IconEmojiName = name
+		assert.NotNil(t, o.IsValid(), "should be invalid with icon emoji %q", name)
+	}
+
+	for _, name := range []string{"", "smile", "+1", "custom_emoji-2"} {
+		o.IconEmojiName = name
+		assert.Nil(t, o.IsValid(), "should be valid with icon emoji %q", name)
+	}
 }
 
 func TestChannelPreSave(t *testing.T) {
@@ -147,6 +213,25 @@ func TestChannelPreUpdate(t *testing.T) {
 	o.PreUpdate()
 }
 
+func TestChannelPreSaveFeed(t *testing.T) {
+	o := Channel{Name: "test", Type: CHANNEL_OPEN, EnableFeed: true}
+	o.PreSave()
+	require.Len(t, o.FeedKey, 26, "enabling a feed should generate a key for it")
+
+	feedKey := o.FeedKey
+	o.PreUpdate()
+	assert.Equal(t, feedKey, o.FeedKey, "the key shouldn't change when the channel is updated")
+
+	o.EnableFeed = false
+	o.PreUpdate()
+	assert.Equal(t, "", o.FeedKey, "disabling a feed should clear its key")
+
+	o = Channel{Name: "test", Type: CHANNEL_PRIVATE, EnableFeed: true, FeedKey: NewId()}
+	o.PreUpdate()
+	assert.False(t, o.EnableFeed, "private channels can't have feeds")
+	assert.Equal(t, "", o.FeedKey)
+}
+
 func TestGetGroupDisplayNameFromUsers(t *testing.T) {
 	users := make([]*User, 4)
 	users[0] = &User{Username: NewId()}
@@ -167,3 +252,42 @@ func TestGetGroupNameFromUserIds(t *testing.T) {
 		t.Fatal("name too long")
 	}
 }
+
+func TestChannelGetOtherUserIdForDM(t *testing.T) {
+	userId1 := NewId()
+	userId2 := NewId()
+	channel := &Channel{Type: CHANNEL_DIRECT, Name: GetDMNameFromIds(userId1, userId2)}
+
+	assert.Equal(t, userId2, channel.GetOtherUserIdForDM(userId1))
+	assert.Equal(t, userId1, channel.GetOtherUserIdForDM(userId2))
+	assert.Equal(t, "", channel.GetOtherUserIdForDM(NewId()))
+
+	self := &Channel{Type: CHANNEL_DIRECT, Name: GetDMNameFromIds(userId1, userId1)}
+	assert.Equal(t, userId1, self.GetOtherUserIdForDM(userId1))
+
+	open := &Channel{Type: CHANNEL_OPEN, Name: GetDMNameFromIds(userId1, userId2)}
+	assert.Equal(t, "", open.GetOtherUserIdForDM(userId1))
+}
+
+func TestChannelChanges(t *testing.T) {
+	old := Channel{Id: NewId(), DisplayName: "Name", Header: strings.Repeat("h", CHANNEL_HEADER_MAX_RUNES), UpdateAt: 1}
+
+	channel := old
+	channel.DisplayName = "New Name"
+	channel.UpdateAt = 2
+
+	assert.Equal(t, map[string]interface{}{"display_name": "New Name", "update_at": float64(2)}, channel.Changes(&old))
+	assert.Empty(t, old.Changes(&old))
+}
+
+func TestChannelIsValidHeaderLinks(t *testing.T) {
+	o := Channel{Id: NewId(), CreateAt: 1, UpdateAt: 1, Name: "valid", DisplayName: "Valid", Type: CHANNEL_OPEN}
+
+	o.Header = "[runbook](https://wiki.example.com/runbook)"
+	assert.Nil(t, o.IsValid())
+
+	o.Header = "[runbook](javascript:alert(document.cookie))"
+	err := o.IsValid()
+	require.NotNil(t, err)
+	assert.Equal(t, "model.channel.is_valid.header_link.app_error", err.Id)
+}
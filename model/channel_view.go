@@ -39,3 +39,10 @@ func ChannelViewResponseFromJson(data io.Reader) *ChannelViewResponse {
 	json.NewDecoder(data).Decode(&o)
 	return o
 }
+
+// ChannelViewedAt is what a channel member's read position is set to when they view the channel.
+type ChannelViewedAt struct {
+	ChannelId     string
+	LastPostAt    int64
+	TotalMsgCount int64
+}
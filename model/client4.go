@@ -18,8 +18,11 @@ import (
 
 const (
 	HEADER_REQUEST_ID         = "X-Request-ID"
+	HEADER_SERVER_TIMING      = "Server-Timing"
 	HEADER_VERSION_ID         = "X-Version-ID"
 	HEADER_CLUSTER_ID         = "X-Cluster-ID"
+	HEADER_DRAINING           = "X-Mattermost-Draining"
+	HEADER_POST_ID            = "X-Post-ID"
 	HEADER_ETAG_SERVER        = "ETag"
 	HEADER_ETAG_CLIENT        = "If-None-Match"
 	HEADER_FORWARDED          = "X-Forwarded-For"
@@ -33,6 +36,7 @@ const (
 	STATUS                    = "status"
 	STATUS_OK                 = "OK"
 	STATUS_FAIL               = "FAIL"
+	STATUS_MAINTENANCE        = "MAINTENANCE"
 	STATUS_REMOVE             = "REMOVE"
 
 	CLIENT_DIR = "client"
@@ -176,6 +180,14 @@ func (c *Client4) GetTeamStatsRoute(teamId string) string {
 	return fmt.Sprintf(c.GetTeamRoute(teamId) + "/stats")
 }
 
+func (c *Client4) GetTeamDefaultChannelsRoute(teamId string) string {
+	return fmt.Sprintf(c.GetTeamsRoute()+"/%v/default_channels", teamId)
+}
+
+func (c *Client4) GetTeamFilteredWordsRoute(teamId string) string {
+	return fmt.Sprintf(c.GetTeamsRoute()+"/%v/filtered_words", teamId)
+}
+
 func (c *Client4) GetTeamImportRoute(teamId string) string {
 	return fmt.Sprintf(c.GetTeamRoute(teamId) + "/import")
 }
@@ -296,6 +308,22 @@ func (c *Client4) GetPreferencesRoute(userId string) string {
 	return fmt.Sprintf(c.GetUserRoute(userId) + "/preferences")
 }
 
+func (c *Client4) GetSavedSearchesRoute(userId string) string {
+	return c.GetUserRoute(userId) + "/saved_searches"
+}
+
+func (c *Client4) GetChannelCategoriesRoute(userId, teamId string) string {
+	return fmt.Sprintf(c.GetUserRoute(userId)+"/teams/%v/channels/categories", teamId)
+}
+
+func (c *Client4) GetUserThreadsRoute(userId, teamId string) string {
+	return fmt.Sprintf(c.GetUserRoute(userId)+"/teams/%v/threads", teamId)
+}
+
+func (c *Client4) GetUserThreadRoute(userId, teamId, threadId string) string {
+	return fmt.Sprintf(c.GetUserThreadsRoute(userId, teamId)+"/%v", threadId)
+}
+
 func (c *Client4) GetUserStatusRoute(userId string) string {
 	return fmt.Sprintf(c.GetUserRoute(userId) + "/status")
 }
@@ -368,6 +396,38 @@ func (c *Client4) GetRolesRoute() string {
 	return fmt.Sprintf("/roles")
 }
 
+func (c *Client4) GetCustomGroupsRoute() string {
+	return fmt.Sprintf("/groups/custom")
+}
+
+func (c *Client4) GetCustomGroupRoute(groupId string) string {
+	return fmt.Sprintf(c.GetCustomGroupsRoute()+"/%v", groupId)
+}
+
+func (c *Client4) GetUserAttributeFieldsRoute() string {
+	return c.GetUsersRoute() + "/attributes/fields"
+}
+
+func (c *Client4) GetUserAttributeFieldRoute(fieldId string) string {
+	return fmt.Sprintf(c.GetUserAttributeFieldsRoute()+"/%v", fieldId)
+}
+
+func (c *Client4) GetProvisioningTokensRoute() string {
+	return fmt.Sprintf("/provisioning_tokens")
+}
+
+func (c *Client4) GetProvisioningTokenRoute(tokenId string) string {
+	return fmt.Sprintf(c.GetProvisioningTokensRoute()+"/%v", tokenId)
+}
+
+func (c *Client4) GetTeamTemplatesRoute() string {
+	return fmt.Sprintf("/team_templates")
+}
+
+func (c *Client4) GetTeamTemplateRoute(templateId string) string {
+	return fmt.Sprintf(c.GetTeamTemplatesRoute()+"/%v", templateId)
+}
+
 func (c *Client4) GetAnalyticsRoute() string {
 	return fmt.Sprintf("/analytics")
 }
@@ -376,6 +436,14 @@ func (c *Client4) GetTimezonesRoute() string {
 	return fmt.Sprintf(c.GetSystemRoute() + "/timezones")
 }
 
+func (c *Client4) GetTermsOfServiceRoute() string {
+	return fmt.Sprintf("/terms_of_service")
+}
+
+func (c *Client4) GetUserTermsOfServiceRoute(userId string) string {
+	return c.GetUserRoute(userId) + "/terms_of_service"
+}
+
 func (c *Client4) DoApiGet(url string, etag string) (*http.Response, *AppError) {
 	return c.DoApiRequest(http.MethodGet, c.ApiUrl+url, "", etag)
 }
@@ -392,7 +460,7 @@ func (c *Client4) DoApiDelete(url string) (*http.Response, *AppError) {
 	return c.DoApiRequest(http.MethodDelete, c.ApiUrl+url, "", "")
 }
 
-func (c *Client4) DoApiRequest(method, url, data, etag string) (*http.Response, *AppError) {
+func (c *Client4) newApiRequest(method, url, data, etag string) *http.Request {
 	rq, _ := http.NewRequest(method, url, strings.NewReader(data))
 	rq.Close = true
 
@@ -404,7 +472,11 @@ func (c *Client4) DoApiRequest(method, url, data, etag string) (*http.Response,
 		rq.Header.Set(HEADER_AUTH, c.AuthType+" "+c.AuthToken)
 	}
 
-	if rp, err := c.HttpClient.Do(rq); err != nil || rp == nil {
+	return rq
+}
+
+func (c *Client4) DoApiRequest(method, url, data, etag string) (*http.Response, *AppError) {
+	if rp, err := c.HttpClient.Do(c.newApiRequest(method, url, data, etag)); err != nil || rp == nil {
 		return nil, NewAppError(url, "model.client.connecting.app_error", nil, err.Error(), 0)
 	} else if rp.StatusCode == 304 {
 		return rp, nil
@@ -534,6 +606,16 @@ func (c *Client4) LoginWithDevice(loginId string, password string, deviceId stri
 	return c.login(m)
 }
 
+// LoginWithCaptcha authenticates a user by login id and password along with the token
+// that the client got for solving a CAPTCHA, which is needed once too many logins have failed.
+func (c *Client4) LoginWithCaptcha(loginId string, password string, captchaToken string) (*User, *Response) {
+	m := make(map[string]string)
+	m["login_id"] = loginId
+	m["password"] = password
+	m["captcha_token"] = captchaToken
+	return c.login(m)
+}
+
 func (c *Client4) login(m map[string]string) (*User, *Response) {
 	if r, err := c.DoApiPost("/users/login", MapToJson(m)); err != nil {
 		return nil, BuildErrorResponse(r, err)
@@ -614,6 +696,16 @@ func (c *Client4) CreateUserWithInviteId(user *User, inviteId string) (*User, *R
 	}
 }
 
+// CreateUserWithInviteLink creates a user and adds them to the team that an invite link is for.
+func (c *Client4) CreateUserWithInviteLink(user *User, linkId string) (*User, *Response) {
+	if r, err := c.DoApiPost(c.GetUsersRoute()+"?lid="+url.QueryEscape(linkId), user.ToJson()); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return UserFromJson(r.Body), BuildResponse(r)
+	}
+}
+
 // GetMe returns the logged in user.
 func (c *Client4) GetMe(etag string) (*User, *Response) {
 	if r, err := c.DoApiGet(c.GetUserRoute(ME), etag); err != nil {
@@ -624,6 +716,17 @@ func (c *Client4) GetMe(etag string) (*User, *Response) {
 	}
 }
 
+// GetBootData returns everything that a client needs when it starts for the currently logged in user. Sections that
+// couldn't be loaded are left out and their errors are in the returned BootData's Errors.
+func (c *Client4) GetBootData() (*BootData, *Response) {
+	if r, err := c.DoApiGet(c.GetUserRoute(ME)+"/boot", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return BootDataFromJson(r.Body), BuildResponse(r)
+	}
+}
+
 // GetUser returns a user based on the provided user id string.
 func (c *Client4) GetUser(userId, etag string) (*User, *Response) {
 	if r, err := c.DoApiGet(c.GetUserRoute(userId), etag); err != nil {
@@ -687,6 +790,18 @@ func (c *Client4) AutocompleteUsers(username string, etag string) (*UserAutocomp
 	}
 }
 
+// AutocompleteUsersInChannelWithOptions returns the users in a channel based on search term, optionally including
+// deactivated users or leaving out bots.
+func (c *Client4) AutocompleteUsersInChannelWithOptions(teamId string, channelId string, username string, allowInactive bool, excludeBots bool) (*UserAutocomplete, *Response) {
+	query := fmt.Sprintf("?in_team=%v&in_channel=%v&name=%v&allow_inactive=%v&exclude_bots=%v", teamId, channelId, username, allowInactive, excludeBots)
+	if r, err := c.DoApiGet(c.GetUsersRoute()+"/autocomplete"+query, ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return UserAutocompleteFromJson(r.Body), BuildResponse(r)
+	}
+}
+
 // GetProfileImage gets user's profile image. Must be logged in or be a system administrator.
 func (c *Client4) GetProfileImage(userId, etag string) ([]byte, *Response) {
 	if r, err := c.DoApiGet(c.GetUserRoute(userId)+"/image", etag); err != nil {
@@ -713,6 +828,81 @@ func (c *Client4) GetUsers(page int, perPage int, etag string) ([]*User, *Respon
 	}
 }
 
+// GetUsersWithFields is like GetUsers, but only the given fields of each user are returned. The fields must be in
+// USER_SPARSE_FIELDS.
+func (c *Client4) GetUsersWithFields(page int, perPage int, fields []string) ([]*User, *Response) {
+	query := fmt.Sprintf("?page=%v&per_page=%v&fields=%v", page, perPage, url.QueryEscape(strings.Join(fields, ",")))
+	if r, err := c.DoApiGet(c.GetUsersRoute()+query, ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return UserListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetUsersWithFilter returns a page of users matching the filter. Page counting starts at 0. Must be authenticated
+// as a system admin.
+func (c *Client4) GetUsersWithFilter(filter *UserFilter, page int, perPage int) ([]*User, *Response) {
+	query := userFilterToQuery(filter)
+	query.Set("page", strconv.Itoa(page))
+	query.Set("per_page", strconv.Itoa(perPage))
+	if r, err := c.DoApiGet(c.GetUsersRoute()+"?"+query.Encode(), ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return UserListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetUsersStats returns the number of users matching the filter. Must be authenticated as a system admin.
+func (c *Client4) GetUsersStats(filter *UserFilter) (*UsersStats, *Response) {
+	query := userFilterToQuery(filter)
+	if r, err := c.DoApiGet(c.GetUsersRoute()+"/stats?"+query.Encode(), ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return UsersStatsFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetUsernamePolicyViolations returns the active users that break the username policy as it's configured.
+func (c *Client4) GetUsernamePolicyViolations() ([]*UsernamePolicyViolation, *Response) {
+	if r, err := c.DoApiGet(c.GetUsersRoute()+"/username_policy/violations", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return UsernamePolicyViolationListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+func userFilterToQuery(filter *UserFilter) url.Values {
+	query := url.Values{}
+	if filter.TeamId != "" {
+		query.Set("in_team", filter.TeamId)
+	}
+	if filter.Role != "" {
+		query.Set("role", filter.Role)
+	}
+	if filter.AuthService != "" {
+		query.Set("auth_service", filter.AuthService)
+	}
+	if filter.Active != nil {
+		query.Set("active", strconv.FormatBool(*filter.Active))
+	}
+	for param, value := range map[string]int64{
+		"created_after":    filter.CreatedAfter,
+		"created_before":   filter.CreatedBefore,
+		"last_seen_after":  filter.LastSeenAfter,
+		"last_seen_before": filter.LastSeenBefore,
+	} {
+		if value != 0 {
+			query.Set(param, strconv.FormatInt(value, 10))
+		}
+	}
+
+	return query
+}
+
 // GetUsersInTeam returns a page of users on a team. Page counting starts at 0.
 func (c *Client4) GetUsersInTeam(teamId string, page int, perPage int, etag string) ([]*User, *Response) {
 	query := fmt.Sprintf("?in_team=%v&page=%v&per_page=%v", teamId, page, perPage)
@@ -942,6 +1132,49 @@ func (c *Client4) UpdateUserActive(userId string, active bool) (bool, *Response)
 	}
 }
 
+// ScheduleUserDeactivation deactivates the user at deactivateAt, given in milliseconds since the epoch.
+func (c *Client4) ScheduleUserDeactivation(userId string, deactivateAt int64) (*User, *Response) {
+	requestBody := make(map[string]interface{})
+	requestBody["deactivate_at"] = deactivateAt
+
+	if r, err := c.DoApiPut(c.GetUserRoute(userId)+"/deactivate_schedule", StringInterfaceToJson(requestBody)); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return UserFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// CancelUserDeactivation removes the scheduled deactivation of the user.
+func (c *Client4) CancelUserDeactivation(userId string) (*User, *Response) {
+	if r, err := c.DoApiDelete(c.GetUserRoute(userId) + "/deactivate_schedule"); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return UserFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// PromoteGuestToUser turns a guest into a regular user.
+func (c *Client4) PromoteGuestToUser(guestId string) (bool, *Response) {
+	if r, err := c.DoApiPost(c.GetUserRoute(guestId)+"/promote", ""); err != nil {
+		return false, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CheckStatusOK(r), BuildResponse(r)
+	}
+}
+
+// DemoteUserToGuest turns a regular user into a guest.
+func (c *Client4) DemoteUserToGuest(userId string) (bool, *Response) {
+	if r, err := c.DoApiPost(c.GetUserRoute(userId)+"/demote", ""); err != nil {
+		return false, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CheckStatusOK(r), BuildResponse(r)
+	}
+}
+
 // DeleteUser deactivates a user in the system based on the provided user id string.
 func (c *Client4) DeleteUser(userId string) (bool, *Response) {
 	if r, err := c.DoApiDelete(c.GetUserRoute(userId)); err != nil {
@@ -1006,6 +1239,17 @@ func (c *Client4) RevokeAllSessions(userId string) (bool, *Response) {
 	}
 }
 
+// ImpersonateUser creates a session that lets a system admin act as the user with the given id. The
+// returned session includes the token to use it with.
+func (c *Client4) ImpersonateUser(userId string) (*Session, *Response) {
+	if r, err := c.DoApiPost(c.GetUserRoute(userId)+"/sessions/impersonate", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return SessionFromJson(r.Body), BuildResponse(r)
+	}
+}
+
 // AttachDeviceId attaches a mobile device ID to the current session.
 func (c *Client4) AttachDeviceId(deviceId string) (bool, *Response) {
 	requestBody := map[string]string{"device_id": deviceId}
@@ -1056,6 +1300,17 @@ func (c *Client4) VerifyUserEmail(token string) (bool, *Response) {
 	}
 }
 
+// UndoEmailChange cancels or reverts a change to a user's email address using the token sent to their old address.
+func (c *Client4) UndoEmailChange(token string) (bool, *Response) {
+	requestBody := map[string]string{"token": token}
+	if r, err := c.DoApiPost(c.GetUsersRoute()+"/email/undo_change", MapToJson(requestBody)); err != nil {
+		return false, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CheckStatusOK(r), BuildResponse(r)
+	}
+}
+
 // SendVerificationEmail will send an email to the user with the provided email address, if
 // that user exists. The email will contain a link that can be used to verify the user's
 // email address.
@@ -1447,6 +1702,48 @@ func (c *Client4) GetTeamStats(teamId, etag string) (*TeamStats, *Response) {
 	}
 }
 
+// GetTeamDefaultChannels returns the ids of the channels, other than Town Square and Off-Topic,
+// that new members of a team are added to.
+func (c *Client4) GetTeamDefaultChannels(teamId, etag string) ([]string, *Response) {
+	if r, err := c.DoApiGet(c.GetTeamDefaultChannelsRoute(teamId), etag); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ArrayFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// UpdateTeamDefaultChannels replaces the channels that new members of a team are added to.
+func (c *Client4) UpdateTeamDefaultChannels(teamId string, channelIds []string) ([]string, *Response) {
+	if r, err := c.DoApiPut(c.GetTeamDefaultChannelsRoute(teamId), ArrayToJson(channelIds)); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ArrayFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetTeamFilteredWords returns the words that are filtered from posts on a team, other than the ones filtered
+// everywhere by the config.
+func (c *Client4) GetTeamFilteredWords(teamId string) ([]string, *Response) {
+	if r, err := c.DoApiGet(c.GetTeamFilteredWordsRoute(teamId), ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ArrayFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// UpdateTeamFilteredWords replaces the words that are filtered from posts on a team.
+func (c *Client4) UpdateTeamFilteredWords(teamId string, words []string) ([]string, *Response) {
+	if r, err := c.DoApiPut(c.GetTeamFilteredWordsRoute(teamId), ArrayToJson(words)); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ArrayFromJson(r.Body), BuildResponse(r)
+	}
+}
+
 // GetTeamUnread will return a TeamUnread object that contains the amount of
 // unread messages and mentions the user has for the specified team.
 // Must be authenticated.
@@ -1499,6 +1796,106 @@ func (c *Client4) InviteUsersToTeam(teamId string, userEmails []string) (bool, *
 	}
 }
 
+// InviteGuestsToTeam invites guests by email to the given channels of a team.
+func (c *Client4) InviteGuestsToTeam(teamId string, userEmails []string, channels []string, message string) (bool, *Response) {
+	guestsInvite := GuestsInvite{
+		Emails:   userEmails,
+		Channels: channels,
+		Message:  message,
+	}
+
+	if r, err := c.DoApiPost(c.GetTeamRoute(teamId)+"/invite-guests/email", guestsInvite.ToJson()); err != nil {
+		return false, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CheckStatusOK(r), BuildResponse(r)
+	}
+}
+
+// SendTeamInvites sends invitations to join a team, each with its own role and channels. Invites that
+// couldn't be sent are reported in the results with an error.
+func (c *Client4) SendTeamInvites(teamId string, invites []*Invite) ([]*InviteResult, *Response) {
+	if r, err := c.DoApiPost(c.GetTeamRoute(teamId)+"/invites", InviteListToJson(invites)); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return InviteResultListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetTeamInvitations returns a page of the invitations that were sent for a team, with whether each one was sent.
+func (c *Client4) GetTeamInvitations(teamId string, page, perPage int) ([]*TeamInvitation, *Response) {
+	query := fmt.Sprintf("?page=%v&per_page=%v", page, perPage)
+	if r, err := c.DoApiGet(c.GetTeamRoute(teamId)+"/invites"+query, ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return TeamInvitationListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// ResendTeamInvitation queues an invitation that failed to be sent to be sent again.
+func (c *Client4) ResendTeamInvitation(teamId, invitationId string) (*TeamInvitation, *Response) {
+	if r, err := c.DoApiPost(c.GetTeamRoute(teamId)+"/invites/"+invitationId+"/resend", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return TeamInvitationFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// CreateInviteLink creates a link for joining a team that can be limited to a number of uses and
+// expire.
+func (c *Client4) CreateInviteLink(teamId string, link *InviteLink) (*InviteLink, *Response) {
+	if r, err := c.DoApiPost(c.GetTeamRoute(teamId)+"/invite_links", link.ToJson()); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return InviteLinkFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetInviteLinks returns all of a team's invite links, including revoked ones, along with how many
+// times they've been used.
+func (c *Client4) GetInviteLinks(teamId string) ([]*InviteLink, *Response) {
+	if r, err := c.DoApiGet(c.GetTeamRoute(teamId)+"/invite_links", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return InviteLinkListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// RevokeInviteLink stops an invite link from being used any further.
+func (c *Client4) RevokeInviteLink(teamId, linkId string) (bool, *Response) {
+	if r, err := c.DoApiDelete(c.GetTeamRoute(teamId) + "/invite_links/" + linkId); err != nil {
+		return false, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CheckStatusOK(r), BuildResponse(r)
+	}
+}
+
+// GetInviteLinkRedemptions returns which users have joined a team with an invite link.
+func (c *Client4) GetInviteLinkRedemptions(teamId, linkId string) ([]*InviteLinkRedemption, *Response) {
+	if r, err := c.DoApiGet(c.GetTeamRoute(teamId)+"/invite_links/"+linkId+"/redemptions", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return InviteLinkRedemptionListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// AddTeamMemberFromInviteLink adds the logged in user to the team that an invite link is for.
+func (c *Client4) AddTeamMemberFromInviteLink(linkId string) (*TeamMember, *Response) {
+	if r, err := c.DoApiPost(c.GetTeamsRoute()+"/members/invite?link_id="+url.QueryEscape(linkId), ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return TeamMemberFromJson(r.Body), BuildResponse(r)
+	}
+}
+
 // GetTeamInviteInfo returns a team object from an invite id containing sanitized information.
 func (c *Client4) GetTeamInviteInfo(inviteId string) (*Team, *Response) {
 	if r, err := c.DoApiGet(c.GetTeamsRoute()+"/invite/"+inviteId, ""); err != nil {
@@ -1572,48 +1969,236 @@ func (c *Client4) RemoveTeamIcon(teamId string) (bool, *Response) {
 	}
 }
 
-// Channel Section
-
-// CreateChannel creates a channel based on the provided channel struct.
-func (c *Client4) CreateChannel(channel *Channel) (*Channel, *Response) {
-	if r, err := c.DoApiPost(c.GetChannelsRoute(), channel.ToJson()); err != nil {
+// GetTeamBranding gets the branding of the team.
+func (c *Client4) GetTeamBranding(teamId, etag string) (*TeamBranding, *Response) {
+	if r, err := c.DoApiGet(c.GetTeamRoute(teamId)+"/branding", etag); err != nil {
 		return nil, BuildErrorResponse(r, err)
 	} else {
 		defer closeBody(r)
-		return ChannelFromJson(r.Body), BuildResponse(r)
+		return TeamBrandingFromJson(r.Body), BuildResponse(r)
 	}
 }
 
-// UpdateChannel update a channel based on the provided channel struct.
-func (c *Client4) UpdateChannel(channel *Channel) (*Channel, *Response) {
-	if r, err := c.DoApiPut(c.GetChannelRoute(channel.Id), channel.ToJson()); err != nil {
+// PatchTeamBranding partially updates the branding of the team. Any missing fields are not updated.
+func (c *Client4) PatchTeamBranding(teamId string, patch *TeamBrandingPatch) (*TeamBranding, *Response) {
+	if r, err := c.DoApiPut(c.GetTeamRoute(teamId)+"/branding/patch", patch.ToJson()); err != nil {
 		return nil, BuildErrorResponse(r, err)
 	} else {
 		defer closeBody(r)
-		return ChannelFromJson(r.Body), BuildResponse(r)
+		return TeamBrandingFromJson(r.Body), BuildResponse(r)
 	}
 }
 
-// PatchChannel partially updates a channel. Any missing fields are not updated.
-func (c *Client4) PatchChannel(channelId string, patch *ChannelPatch) (*Channel, *Response) {
-	if r, err := c.DoApiPut(c.GetChannelRoute(channelId)+"/patch", patch.ToJson()); err != nil {
-		return nil, BuildErrorResponse(r, err)
-	} else {
-		defer closeBody(r)
-		return ChannelFromJson(r.Body), BuildResponse(r)
+// SetTeamBrandingImage sets the branding image of the team.
+func (c *Client4) SetTeamBrandingImage(teamId string, data []byte) (bool, *Response) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	if part, err := writer.CreateFormFile("image", "branding.png"); err != nil {
+		return false, &Response{Error: NewAppError("SetTeamBrandingImage", "model.client.set_team_branding_image.no_file.app_error", nil, err.Error(), http.StatusBadRequest)}
+	} else if _, err = io.Copy(part, bytes.NewBuffer(data)); err != nil {
+		return false, &Response{Error: NewAppError("SetTeamBrandingImage", "model.client.set_team_branding_image.no_file.app_error", nil, err.Error(), http.StatusBadRequest)}
 	}
-}
 
-// ConvertChannelToPrivate converts public to private channel.
-func (c *Client4) ConvertChannelToPrivate(channelId string) (*Channel, *Response) {
-	if r, err := c.DoApiPost(c.GetChannelRoute(channelId)+"/convert", ""); err != nil {
-		return nil, BuildErrorResponse(r, err)
+	if err := writer.Close(); err != nil {
+		return false, &Response{Error: NewAppError("SetTeamBrandingImage", "model.client.set_team_branding_image.writer.app_error", nil, err.Error(), http.StatusBadRequest)}
+	}
+
+	rq, _ := http.NewRequest("POST", c.ApiUrl+c.GetTeamRoute(teamId)+"/branding/image", bytes.NewReader(body.Bytes()))
+	rq.Header.Set("Content-Type", writer.FormDataContentType())
+	rq.Close = true
+
+	if len(c.AuthToken) > 0 {
+		rq.Header.Set(HEADER_AUTH, c.AuthType+" "+c.AuthToken)
+	}
+
+	if rp, err := c.HttpClient.Do(rq); err != nil || rp == nil {
+		return false, &Response{StatusCode: http.StatusForbidden, Error: NewAppError(c.GetTeamRoute(teamId)+"/branding/image", "model.client.connecting.app_error", nil, err.Error(), http.StatusForbidden)}
+	} else {
+		defer closeBody(rp)
+
+		if rp.StatusCode >= 300 {
+			return false, BuildErrorResponse(rp, AppErrorFromJson(rp.Body))
+		} else {
+			return CheckStatusOK(rp), BuildResponse(rp)
+		}
+	}
+}
+
+// GetTeamBrandingImage gets the branding image of the team, or the site's brand image if the team doesn't have one.
+func (c *Client4) GetTeamBrandingImage(teamId, etag string) ([]byte, *Response) {
+	if r, err := c.DoApiGet(c.GetTeamRoute(teamId)+"/branding/image", etag); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+
+		if data, err := ioutil.ReadAll(r.Body); err != nil {
+			return nil, BuildErrorResponse(r, NewAppError("GetTeamBrandingImage", "model.client.get_team_branding_image.app_error", nil, err.Error(), r.StatusCode))
+		} else {
+			return data, BuildResponse(r)
+		}
+	}
+}
+
+// RemoveTeamBrandingImage removes the branding image of the team so that the site's brand image is used instead.
+func (c *Client4) RemoveTeamBrandingImage(teamId string) (bool, *Response) {
+	if r, err := c.DoApiDelete(c.GetTeamRoute(teamId) + "/branding/image"); err != nil {
+		return false, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CheckStatusOK(r), BuildResponse(r)
+	}
+}
+
+// Channel Section
+
+// CreateChannel creates a channel based on the provided channel struct.
+func (c *Client4) CreateChannel(channel *Channel) (*Channel, *Response) {
+	if r, err := c.DoApiPost(c.GetChannelsRoute(), channel.ToJson()); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ChannelFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// UpdateChannel update a channel based on the provided channel struct.
+func (c *Client4) UpdateChannel(channel *Channel) (*Channel, *Response) {
+	if r, err := c.DoApiPut(c.GetChannelRoute(channel.Id), channel.ToJson()); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ChannelFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// PatchChannel partially updates a channel. Any missing fields are not updated.
+func (c *Client4) PatchChannel(channelId string, patch *ChannelPatch) (*Channel, *Response) {
+	if r, err := c.DoApiPut(c.GetChannelRoute(channelId)+"/patch", patch.ToJson()); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ChannelFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// SetChannelIcon uploads an icon for the channel, replacing its emoji if it has one.
+func (c *Client4) SetChannelIcon(channelId string, data []byte) (bool, *Response) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	if part, err := writer.CreateFormFile("image", "channelIcon.png"); err != nil {
+		return false, &Response{Error: NewAppError("SetChannelIcon", "model.client.set_channel_icon.no_file.app_error", nil, err.Error(), http.StatusBadRequest)}
+	} else if _, err = io.Copy(part, bytes.NewBuffer(data)); err != nil {
+		return false, &Response{Error: NewAppError("SetChannelIcon", "model.client.set_channel_icon.no_file.app_error", nil, err.Error(), http.StatusBadRequest)}
+	}
+
+	if err := writer.Close(); err != nil {
+		return false, &Response{Error: NewAppError("SetChannelIcon", "model.client.set_channel_icon.writer.app_error", nil, err.Error(), http.StatusBadRequest)}
+	}
+
+	rq, _ := http.NewRequest("POST", c.ApiUrl+c.GetChannelRoute(channelId)+"/image", bytes.NewReader(body.Bytes()))
+	rq.Header.Set("Content-Type", writer.FormDataContentType())
+	rq.Close = true
+
+	if len(c.AuthToken) > 0 {
+		rq.Header.Set(HEADER_AUTH, c.AuthType+" "+c.AuthToken)
+	}
+
+	if rp, err := c.HttpClient.Do(rq); err != nil || rp == nil {
+		return false, &Response{StatusCode: http.StatusForbidden, Error: NewAppError(c.GetChannelRoute(channelId)+"/image", "model.client.connecting.app_error", nil, err.Error(), http.StatusForbidden)}
+	} else {
+		defer closeBody(rp)
+
+		if rp.StatusCode >= 300 {
+			return false, BuildErrorResponse(rp, AppErrorFromJson(rp.Body))
+		} else {
+			return CheckStatusOK(rp), BuildResponse(rp)
+		}
+	}
+}
+
+// GetChannelIcon gets the icon that was uploaded for the channel.
+func (c *Client4) GetChannelIcon(channelId, etag string) ([]byte, *Response) {
+	if r, err := c.DoApiGet(c.GetChannelRoute(channelId)+"/image", etag); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+
+		if data, err := ioutil.ReadAll(r.Body); err != nil {
+			return nil, BuildErrorResponse(r, NewAppError("GetChannelIcon", "model.client.get_channel_icon.app_error", nil, err.Error(), r.StatusCode))
+		} else {
+			return data, BuildResponse(r)
+		}
+	}
+}
+
+// RemoveChannelIcon stops the channel from showing the icon that was uploaded for it.
+func (c *Client4) RemoveChannelIcon(channelId string) (bool, *Response) {
+	if r, err := c.DoApiDelete(c.GetChannelRoute(channelId) + "/image"); err != nil {
+		return false, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CheckStatusOK(r), BuildResponse(r)
+	}
+}
+
+// ConvertChannelToPrivate converts public to private channel.
+func (c *Client4) ConvertChannelToPrivate(channelId string) (*Channel, *Response) {
+	if r, err := c.DoApiPost(c.GetChannelRoute(channelId)+"/convert", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
 	} else {
 		defer closeBody(r)
 		return ChannelFromJson(r.Body), BuildResponse(r)
 	}
 }
 
+// ConvertChannel makes a channel public or private, returning it along with the integrations that can read from or
+// post to it.
+func (c *Client4) ConvertChannel(channelId string, channelType string) (*ChannelConversion, *Response) {
+	if r, err := c.DoApiPost(c.GetChannelRoute(channelId)+"/convert", MapToJson(map[string]string{"type": channelType})); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ChannelConversionFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetChannelFeed returns the address of a channel's feed, including the token needed to read it.
+func (c *Client4) GetChannelFeed(channelId string) (*ChannelFeed, *Response) {
+	if r, err := c.DoApiGet(c.GetChannelRoute(channelId)+"/feed", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ChannelFeedFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// RegenerateChannelFeedToken replaces the token for a channel's feed, revoking the old one.
+func (c *Client4) RegenerateChannelFeedToken(channelId string) (*ChannelFeed, *Response) {
+	if r, err := c.DoApiPost(c.GetChannelRoute(channelId)+"/feed/regenerate_token", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ChannelFeedFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetChannelFeedAtom returns a channel's Atom feed. It doesn't need the client to be logged in.
+func (c *Client4) GetChannelFeedAtom(channelId string, token string, etag string) ([]byte, *Response) {
+	if r, err := c.DoApiGet(c.GetChannelRoute(channelId)+"/feed.atom?token="+url.QueryEscape(token), etag); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+
+		if data, err := ioutil.ReadAll(r.Body); err != nil {
+			return nil, BuildErrorResponse(r, NewAppError("GetChannelFeedAtom", "model.client.read_file.app_error", nil, err.Error(), r.StatusCode))
+		} else {
+			return data, BuildResponse(r)
+		}
+	}
+}
+
 // RestoreChannel restores a previously deleted channel. Any missing fields are not updated.
 func (c *Client4) RestoreChannel(channelId string) (*Channel, *Response) {
 	if r, err := c.DoApiPost(c.GetChannelRoute(channelId)+"/restore", ""); err != nil {
@@ -1636,6 +2221,17 @@ func (c *Client4) CreateDirectChannel(userId1, userId2 string) (*Channel, *Respo
 	}
 }
 
+// GetDirectChannelWithUser returns the direct message channel between the current user and another user. If there
+// isn't one yet, it's only created when create is true.
+func (c *Client4) GetDirectChannelWithUser(userId string, create bool) (*Channel, *Response) {
+	if r, err := c.DoApiGet(c.GetChannelsRoute()+"/direct/"+userId+fmt.Sprintf("?create=%v", create), ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ChannelFromJson(r.Body), BuildResponse(r)
+	}
+}
+
 // CreateGroupChannel creates a group message channel based on userIds provided
 func (c *Client4) CreateGroupChannel(userIds []string) (*Channel, *Response) {
 	if r, err := c.DoApiPost(c.GetChannelsRoute()+"/group", ArrayToJson(userIds)); err != nil {
@@ -1666,6 +2262,46 @@ func (c *Client4) GetChannelStats(channelId string, etag string) (*ChannelStats,
 	}
 }
 
+// GetChannelModerations gets the moderation settings of a channel.
+func (c *Client4) GetChannelModerations(channelId string, etag string) ([]*ChannelModeration, *Response) {
+	if r, err := c.DoApiGet(c.GetChannelRoute(channelId)+"/moderations", etag); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ChannelModerationsFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// PatchChannelModerations updates the moderation settings of a channel and returns all of them.
+func (c *Client4) PatchChannelModerations(channelId string, patches []*ChannelModerationPatch) ([]*ChannelModeration, *Response) {
+	if r, err := c.DoApiPut(c.GetChannelRoute(channelId)+"/moderations", ChannelModerationsPatchToJson(patches)); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ChannelModerationsFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetChannelAliases gets the old names of a channel that still redirect to it, newest first.
+func (c *Client4) GetChannelAliases(channelId string, etag string) ([]*ChannelNameHistory, *Response) {
+	if r, err := c.DoApiGet(c.GetChannelRoute(channelId)+"/aliases", etag); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ChannelNameHistoryListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// DeleteChannelAlias stops one of a channel's old names from redirecting to it.
+func (c *Client4) DeleteChannelAlias(channelId string, name string) (bool, *Response) {
+	if r, err := c.DoApiDelete(c.GetChannelRoute(channelId) + "/aliases/" + name); err != nil {
+		return false, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CheckStatusOK(r), BuildResponse(r)
+	}
+}
+
 // GetPinnedPosts gets a list of pinned posts.
 func (c *Client4) GetPinnedPosts(channelId string, etag string) (*PostList, *Response) {
 	if r, err := c.DoApiGet(c.GetChannelRoute(channelId)+"/pinned", etag); err != nil {
@@ -1687,6 +2323,18 @@ func (c *Client4) GetPublicChannelsForTeam(teamId string, page int, perPage int,
 	}
 }
 
+// GetPublicChannelsForTeamWithFields is like GetPublicChannelsForTeam, but only the given fields of each channel are
+// returned. The fields must be in CHANNEL_SPARSE_FIELDS.
+func (c *Client4) GetPublicChannelsForTeamWithFields(teamId string, page int, perPage int, fields []string) ([]*Channel, *Response) {
+	query := fmt.Sprintf("?page=%v&per_page=%v&fields=%v", page, perPage, url.QueryEscape(strings.Join(fields, ",")))
+	if r, err := c.DoApiGet(c.GetChannelsForTeamRoute(teamId)+query, ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ChannelSliceFromJson(r.Body), BuildResponse(r)
+	}
+}
+
 // GetDeletedChannelsForTeam returns a list of public channels based on the provided team id string.
 func (c *Client4) GetDeletedChannelsForTeam(teamId string, page int, perPage int, etag string) ([]*Channel, *Response) {
 	query := fmt.Sprintf("/deleted?page=%v&per_page=%v", page, perPage)
@@ -1738,6 +2386,17 @@ func (c *Client4) DeleteChannel(channelId string) (bool, *Response) {
 	}
 }
 
+// PermanentDeleteChannel archives the channel and marks it to be permanently deleted once the server's grace
+// period has passed. It can be restored with RestoreChannel until then.
+func (c *Client4) PermanentDeleteChannel(channelId string) (bool, *Response) {
+	if r, err := c.DoApiDelete(c.GetChannelRoute(channelId) + "?permanent=true"); err != nil {
+		return false, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CheckStatusOK(r), BuildResponse(r)
+	}
+}
+
 // GetChannelByName returns a channel based on the provided channel name and team id strings.
 func (c *Client4) GetChannelByName(channelName, teamId string, etag string) (*Channel, *Response) {
 	if r, err := c.DoApiGet(c.GetChannelByNameRoute(channelName, teamId), etag); err != nil {
@@ -1769,6 +2428,18 @@ func (c *Client4) GetChannelMembers(channelId string, page, perPage int, etag st
 	}
 }
 
+// GetChannelMemberHistory gets a page of the memberships of a channel that were started or ended between since
+// and until, along with who started and ended them. An until of 0 means now. Must have manage_system permission.
+func (c *Client4) GetChannelMemberHistory(channelId string, since, until int64, page, perPage int) ([]*ChannelMemberHistoryResult, *Response) {
+	query := fmt.Sprintf("?since=%v&until=%v&page=%v&per_page=%v", since, until, page, perPage)
+	if r, err := c.DoApiGet(c.GetChannelMembersRoute(channelId)+"/history"+query, ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ChannelMemberHistoryResultListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
 // GetChannelMembersByIds gets the channel members in a channel for a list of user ids.
 func (c *Client4) GetChannelMembersByIds(channelId string, userIds []string) (*ChannelMembers, *Response) {
 	if r, err := c.DoApiPost(c.GetChannelMembersRoute(channelId)+"/ids", ArrayToJson(userIds)); err != nil {
@@ -1822,6 +2493,50 @@ func (c *Client4) GetChannelUnread(channelId, userId string) (*ChannelUnread, *R
 	}
 }
 
+// GetChannelUnreadsForTeamForUser returns the unread message and mention counts of every channel that a user
+// is a member of on a team, including their direct and group message channels.
+func (c *Client4) GetChannelUnreadsForTeamForUser(teamId, userId string) ([]*ChannelUnread, *Response) {
+	if r, err := c.DoApiGet(c.GetUserRoute(userId)+c.GetTeamRoute(teamId)+"/channels/unreads", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ChannelUnreadsFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetChannelUnreadsForUser returns the unread message and mention counts of every channel that a user is a
+// member of across all of their teams.
+func (c *Client4) GetChannelUnreadsForUser(userId string) ([]*ChannelUnread, *Response) {
+	if r, err := c.DoApiGet(c.GetUserRoute(userId)+"/channels/unreads", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ChannelUnreadsFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// MarkChannelsAsReadForTeamForUser marks every channel that a user is a member of on a team as read, including
+// their direct and group message channels, and returns the ids of the channels that had unread messages.
+func (c *Client4) MarkChannelsAsReadForTeamForUser(teamId, userId string) ([]string, *Response) {
+	if r, err := c.DoApiPost(c.GetUserRoute(userId)+c.GetTeamRoute(teamId)+"/channels/read", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ArrayFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// MarkChannelsAsReadForUser marks every channel that a user is a member of across all of their teams as read, and
+// returns the ids of the channels that had unread messages.
+func (c *Client4) MarkChannelsAsReadForUser(userId string) ([]string, *Response) {
+	if r, err := c.DoApiPost(c.GetUserRoute(userId)+"/channels/read", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ArrayFromJson(r.Body), BuildResponse(r)
+	}
+}
+
 // UpdateChannelRoles will update the roles on a channel for a user.
 func (c *Client4) UpdateChannelRoles(channelId, userId, roles string) (bool, *Response) {
 	requestBody := map[string]string{"roles": roles}
@@ -1833,6 +2548,18 @@ func (c *Client4) UpdateChannelRoles(channelId, userId, roles string) (bool, *Re
 	}
 }
 
+// UpdateChannelMembersRoles gives the users the same roles in a channel. Either all of them are changed or, if any
+// of them can't be, none are.
+func (c *Client4) UpdateChannelMembersRoles(channelId string, userIds []string, roles string) (bool, *Response) {
+	requestBody := &ChannelMembersRoles{UserIds: userIds, Roles: roles}
+	if r, err := c.DoApiPut(c.GetChannelMembersRoute(channelId)+"/roles", requestBody.ToJson()); err != nil {
+		return false, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CheckStatusOK(r), BuildResponse(r)
+	}
+}
+
 // UpdateChannelNotifyProps will update the notification properties on a channel for a user.
 func (c *Client4) UpdateChannelNotifyProps(channelId, userId string, props map[string]string) (bool, *Response) {
 	if r, err := c.DoApiPut(c.GetChannelMemberRoute(channelId, userId)+"/notify_props", MapToJson(props)); err != nil {
@@ -1843,6 +2570,17 @@ func (c *Client4) UpdateChannelNotifyProps(channelId, userId string, props map[s
 	}
 }
 
+// UpdateChannelMembersNotifyProps will update the notification properties on several channels at once for a user, taking a
+// map of channel ids to the properties to change in each.
+func (c *Client4) UpdateChannelMembersNotifyProps(userId string, propsByChannel ChannelNotifyPropsByChannel) (bool, *Response) {
+	if r, err := c.DoApiPut(c.GetUserRoute(userId)+"/channels/notify_props", propsByChannel.ToJson()); err != nil {
+		return false, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CheckStatusOK(r), BuildResponse(r)
+	}
+}
+
 // AddChannelMember adds user to channel and return a channel member.
 func (c *Client4) AddChannelMember(channelId, userId string) (*ChannelMember, *Response) {
 	requestBody := map[string]string{"user_id": userId}
@@ -1875,26 +2613,191 @@ func (c *Client4) RemoveUserFromChannel(channelId, userId string) (bool, *Respon
 	}
 }
 
-// AutocompleteChannelsForTeam will return an ordered list of channels autocomplete suggestions
-func (c *Client4) AutocompleteChannelsForTeam(teamId, name string) (*ChannelList, *Response) {
-	query := fmt.Sprintf("?name=%v", name)
-	if r, err := c.DoApiGet(c.GetChannelsForTeamRoute(teamId)+"/autocomplete"+query, ""); err != nil {
+// AddGroupChannelMembers adds users to a group channel and returns the channel with the name and display name that
+// it's given for its new members.
+func (c *Client4) AddGroupChannelMembers(channelId string, userIds []string) (*Channel, *Response) {
+	if r, err := c.DoApiPut(c.GetChannelMembersRoute(channelId), ArrayToJson(userIds)); err != nil {
 		return nil, BuildErrorResponse(r, err)
 	} else {
 		defer closeBody(r)
-		return ChannelListFromJson(r.Body), BuildResponse(r)
+		return ChannelFromJson(r.Body), BuildResponse(r)
 	}
 }
 
-// Post Section
-
-// CreatePost creates a post based on the provided post struct.
-func (c *Client4) CreatePost(post *Post) (*Post, *Response) {
-	if r, err := c.DoApiPost(c.GetPostsRoute(), post.ToUnsanitizedJson()); err != nil {
+// RemoveGroupChannelMembers removes users from a group channel and returns the channel with the name and display
+// name that it's given for its remaining members.
+func (c *Client4) RemoveGroupChannelMembers(channelId string, userIds []string) (*Channel, *Response) {
+	if r, err := c.DoApiRequest(http.MethodDelete, c.ApiUrl+c.GetChannelMembersRoute(channelId), ArrayToJson(userIds), ""); err != nil {
 		return nil, BuildErrorResponse(r, err)
 	} else {
 		defer closeBody(r)
-		return PostFromJson(r.Body), BuildResponse(r)
+		return ChannelFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// AutocompleteChannelsForTeam will return an ordered list of channels autocomplete suggestions
+func (c *Client4) AutocompleteChannelsForTeam(teamId, name string) (*ChannelList, *Response) {
+	query := fmt.Sprintf("?name=%v", name)
+	if r, err := c.DoApiGet(c.GetChannelsForTeamRoute(teamId)+"/autocomplete"+query, ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ChannelListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// Channel Category Section
+
+// GetSidebarCategoriesForTeamForUser returns the sidebar categories for a user on a team, along with the order they should be shown in.
+func (c *Client4) GetSidebarCategoriesForTeamForUser(userId, teamId, etag string) (*OrderedSidebarCategories, *Response) {
+	if r, err := c.DoApiGet(c.GetChannelCategoriesRoute(userId, teamId), etag); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return OrderedSidebarCategoriesFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// CreateSidebarCategoryForTeamForUser creates a new custom sidebar category for a user on a team.
+func (c *Client4) CreateSidebarCategoryForTeamForUser(userId, teamId string, category *SidebarCategoryWithChannels) (*SidebarCategoryWithChannels, *Response) {
+	if r, err := c.DoApiPost(c.GetChannelCategoriesRoute(userId, teamId), category.ToJson()); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return SidebarCategoryWithChannelsFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// UpdateSidebarCategoriesForTeamForUser updates the names and channels of several of a user's sidebar categories at once.
+func (c *Client4) UpdateSidebarCategoriesForTeamForUser(userId, teamId string, categories []*SidebarCategoryWithChannels) ([]*SidebarCategoryWithChannels, *Response) {
+	if r, err := c.DoApiPut(c.GetChannelCategoriesRoute(userId, teamId), SidebarCategoriesWithChannelsToJson(categories)); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return SidebarCategoriesWithChannelsFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetSidebarCategoryOrderForTeamForUser returns the ids of a user's sidebar categories in the order they should be shown in.
+func (c *Client4) GetSidebarCategoryOrderForTeamForUser(userId, teamId, etag string) ([]string, *Response) {
+	if r, err := c.DoApiGet(c.GetChannelCategoriesRoute(userId, teamId)+"/order", etag); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ArrayFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// UpdateSidebarCategoryOrderForTeamForUser changes the order of a user's sidebar categories.
+func (c *Client4) UpdateSidebarCategoryOrderForTeamForUser(userId, teamId string, order []string) ([]string, *Response) {
+	if r, err := c.DoApiPut(c.GetChannelCategoriesRoute(userId, teamId)+"/order", ArrayToJson(order)); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ArrayFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetSidebarCategoryForTeamForUser returns a single sidebar category for a user on a team.
+func (c *Client4) GetSidebarCategoryForTeamForUser(userId, teamId, categoryId, etag string) (*SidebarCategoryWithChannels, *Response) {
+	if r, err := c.DoApiGet(c.GetChannelCategoriesRoute(userId, teamId)+"/"+categoryId, etag); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return SidebarCategoryWithChannelsFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// UpdateSidebarCategoryForTeamForUser updates the name and channels of one of a user's sidebar categories.
+func (c *Client4) UpdateSidebarCategoryForTeamForUser(userId, teamId, categoryId string, category *SidebarCategoryWithChannels) (*SidebarCategoryWithChannels, *Response) {
+	if r, err := c.DoApiPut(c.GetChannelCategoriesRoute(userId, teamId)+"/"+categoryId, category.ToJson()); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return SidebarCategoryWithChannelsFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// DeleteSidebarCategoryForTeamForUser deletes one of a user's custom sidebar categories.
+func (c *Client4) DeleteSidebarCategoryForTeamForUser(userId, teamId, categoryId string) (bool, *Response) {
+	if r, err := c.DoApiDelete(c.GetChannelCategoriesRoute(userId, teamId) + "/" + categoryId); err != nil {
+		return false, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CheckStatusOK(r), BuildResponse(r)
+	}
+}
+
+// Thread Section
+
+// GetUserThreads returns a page of the threads that a user is following on a team, along with their unread totals.
+func (c *Client4) GetUserThreads(userId, teamId string, page, perPage int) (*Threads, *Response) {
+	query := fmt.Sprintf("?page=%v&per_page=%v", page, perPage)
+	if r, err := c.DoApiGet(c.GetUserThreadsRoute(userId, teamId)+query, ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ThreadsFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetUserThread returns a single thread as seen by a user.
+func (c *Client4) GetUserThread(userId, teamId, threadId string) (*ThreadResponse, *Response) {
+	if r, err := c.DoApiGet(c.GetUserThreadRoute(userId, teamId, threadId), ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ThreadResponseFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// UpdateThreadReadForUser marks a thread as read by a user up to the given time.
+func (c *Client4) UpdateThreadReadForUser(userId, teamId, threadId string, timestamp int64) (*ThreadResponse, *Response) {
+	if r, err := c.DoApiPut(fmt.Sprintf("%s/read/%d", c.GetUserThreadRoute(userId, teamId, threadId), timestamp), ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ThreadResponseFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// UpdateThreadFollowForUser starts or stops a user from following a thread.
+func (c *Client4) UpdateThreadFollowForUser(userId, teamId, threadId string, following bool) (bool, *Response) {
+	var r *http.Response
+	var err *AppError
+	if following {
+		r, err = c.DoApiPut(c.GetUserThreadRoute(userId, teamId, threadId)+"/following", "")
+	} else {
+		r, err = c.DoApiDelete(c.GetUserThreadRoute(userId, teamId, threadId) + "/following")
+	}
+
+	if err != nil {
+		return false, BuildErrorResponse(r, err)
+	}
+	defer closeBody(r)
+	return CheckStatusOK(r), BuildResponse(r)
+}
+
+// Post Section
+
+// CreatePost creates a post based on the provided post struct.
+func (c *Client4) CreatePost(post *Post) (*Post, *Response) {
+	if r, err := c.DoApiPost(c.GetPostsRoute(), post.ToUnsanitizedJson()); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return PostFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// CreatePostConfirmingChannelMentions creates a post like CreatePost, confirming that @here, @channel and @all
+// mentions should be posted even when the channel is too large for them to notify anyone.
+func (c *Client4) CreatePostConfirmingChannelMentions(post *Post) (*Post, *Response) {
+	if r, err := c.DoApiPost(c.GetPostsRoute()+"?confirm_channel_mentions=true", post.ToUnsanitizedJson()); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return PostFromJson(r.Body), BuildResponse(r)
 	}
 }
 
@@ -1978,6 +2881,26 @@ func (c *Client4) GetPostThread(postId string, etag string) (*PostList, *Respons
 	}
 }
 
+// GetPostHistory gets the earlier versions of a post from before it was edited, oldest first.
+func (c *Client4) GetPostHistory(postId string) ([]*PostHistory, *Response) {
+	if r, err := c.DoApiGet(c.GetPostRoute(postId)+"/history", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return PostHistoryListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// ForwardPost shares a post in another channel, along with an optional comment.
+func (c *Client4) ForwardPost(postId string, forward *PostForward) (*Post, *Response) {
+	if r, err := c.DoApiPost(c.GetPostRoute(postId)+"/forward", forward.ToJson()); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return PostFromJson(r.Body), BuildResponse(r)
+	}
+}
+
 // GetPostsForChannel gets a page of posts with an array for ordering for a channel.
 func (c *Client4) GetPostsForChannel(channelId string, page, perPage int, etag string) (*PostList, *Response) {
 	query := fmt.Sprintf("?page=%v&per_page=%v", page, perPage)
@@ -1989,6 +2912,51 @@ func (c *Client4) GetPostsForChannel(channelId string, page, perPage int, etag s
 	}
 }
 
+// GetPostsForChannelWithFields is like GetPostsForChannel, but only the given fields of each post are returned. The
+// fields must be in POST_SPARSE_FIELDS.
+func (c *Client4) GetPostsForChannelWithFields(channelId string, page, perPage int, fields []string) (*PostList, *Response) {
+	query := fmt.Sprintf("?page=%v&per_page=%v&fields=%v", page, perPage, url.QueryEscape(strings.Join(fields, ",")))
+	if r, err := c.DoApiGet(c.GetChannelRoute(channelId)+"/posts"+query, ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return PostListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetPostsForChannelWithMetadata is like GetPostsForChannel, but the metadata of each post also includes its file
+// infos, the custom emojis that it uses and an embed for the first link in it.
+func (c *Client4) GetPostsForChannelWithMetadata(channelId string, page, perPage int, etag string) (*PostList, *Response) {
+	query := fmt.Sprintf("?page=%v&per_page=%v&include_metadata=true", page, perPage)
+	if r, err := c.DoApiGet(c.GetChannelRoute(channelId)+"/posts"+query, etag); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return PostListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// SetPostUnread marks a post and everything after it in its channel as unread for a user.
+func (c *Client4) SetPostUnread(userId string, postId string) (*ChannelUnread, *Response) {
+	if r, err := c.DoApiPost(c.GetUserRoute(userId)+"/posts/"+postId+"/set_unread", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ChannelUnreadFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// SetPostRead records that a user has read the channel containing a post up to that post, returning the channel's
+// unread state with its last read post.
+func (c *Client4) SetPostRead(userId string, postId string) (*ChannelUnread, *Response) {
+	if r, err := c.DoApiPost(c.GetUserRoute(userId)+"/posts/"+postId+"/set_read", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ChannelUnreadFromJson(r.Body), BuildResponse(r)
+	}
+}
+
 // GetFlaggedPostsForUser returns flagged posts of a user based on user id string.
 func (c *Client4) GetFlaggedPostsForUser(userId string, page int, perPage int) (*PostList, *Response) {
 	query := fmt.Sprintf("?page=%v&per_page=%v", page, perPage)
@@ -2059,24 +3027,417 @@ func (c *Client4) GetPostsBefore(channelId, postId string, page, perPage int, et
 		return nil, BuildErrorResponse(r, err)
 	} else {
 		defer closeBody(r)
-		return PostListFromJson(r.Body), BuildResponse(r)
+		return PostListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// SearchPosts returns any posts with matching terms string.
+func (c *Client4) SearchPosts(teamId string, terms string, isOrSearch bool) (*PostList, *Response) {
+	requestBody := map[string]interface{}{"terms": terms, "is_or_search": isOrSearch}
+	if r, err := c.DoApiPost(c.GetTeamRoute(teamId)+"/posts/search", StringInterfaceToJson(requestBody)); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return PostListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// SearchPostsWithPaging returns a page of the posts with matching terms string. Pass the SearchAt of the results
+// of the first page when getting the following pages so that posts made in the meantime don't shift them.
+func (c *Client4) SearchPostsWithPaging(teamId string, terms string, isOrSearch bool, searchAt int64, page int, perPage int) (*PostSearchResults, *Response) {
+	requestBody := map[string]interface{}{"terms": terms, "is_or_search": isOrSearch, "search_at": searchAt, "page": page, "per_page": perPage}
+	if r, err := c.DoApiPost(c.GetTeamRoute(teamId)+"/posts/search", StringInterfaceToJson(requestBody)); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return PostSearchResultsFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// DoPostAction performs a post action.
+func (c *Client4) DoPostAction(postId, actionId string) (bool, *Response) {
+	if r, err := c.DoApiPost(c.GetPostRoute(postId)+"/actions/"+actionId, ""); err != nil {
+		return false, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CheckStatusOK(r), BuildResponse(r)
+	}
+}
+
+// File Section
+
+// UploadFile will upload a file to a channel using a multipart request, to be later attached to a post.
+// This method is functionally equivalent to Client4.UploadFileAsRequestBody.
+func (c *Client4) UploadFile(data []byte, channelId string, filename string) (*FileUploadResponse, *Response) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	if part, err := writer.CreateFormFile("files", filename); err != nil {
+		return nil, &Response{Error: NewAppError("UploadPostAttachment", "model.client.upload_post_attachment.file.app_error", nil, err.Error(), http.StatusBadRequest)}
+	} else if _, err = io.Copy(part, bytes.NewBuffer(data)); err != nil {
+		return nil, &Response{Error: NewAppError("UploadPostAttachment", "model.client.upload_post_attachment.file.app_error", nil, err.Error(), http.StatusBadRequest)}
+	}
+
+	if part, err := writer.CreateFormField("channel_id"); err != nil {
+		return nil, &Response{Error: NewAppError("UploadPostAttachment", "model.client.upload_post_attachment.channel_id.app_error", nil, err.Error(), http.StatusBadRequest)}
+	} else if _, err = io.Copy(part, strings.NewReader(channelId)); err != nil {
+		return nil, &Response{Error: NewAppError("UploadPostAttachment", "model.client.upload_post_attachment.channel_id.app_error", nil, err.Error(), http.StatusBadRequest)}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, &Response{Error: NewAppError("UploadPostAttachment", "model.client.upload_post_attachment.writer.app_error", nil, err.Error(), http.StatusBadRequest)}
+	}
+
+	return c.DoUploadFile(c.GetFilesRoute(), body.Bytes(), writer.FormDataContentType())
+}
+
+// UploadFileAsRequestBody will upload a file to a channel as the body of a request, to be later attached
+// to a post. This method is functionally equivalent to Client4.UploadFile.
+func (c *Client4) UploadFileAsRequestBody(data []byte, channelId string, filename string) (*FileUploadResponse, *Response) {
+	return c.DoUploadFile(c.GetFilesRoute()+fmt.Sprintf("?channel_id=%v&filename=%v", url.QueryEscape(channelId), url.QueryEscape(filename)), data, http.DetectContentType(data))
+}
+
+// GetFile gets the bytes for a file by id.
+func (c *Client4) GetFile(fileId string) ([]byte, *Response) {
+	if r, err := c.DoApiGet(c.GetFileRoute(fileId), ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+
+		if data, err := ioutil.ReadAll(r.Body); err != nil {
+			return nil, BuildErrorResponse(r, NewAppError("GetFile", "model.client.read_file.app_error", nil, err.Error(), r.StatusCode))
+		} else {
+			return data, BuildResponse(r)
+		}
+	}
+}
+
+// DownloadFile gets the bytes for a file by id, optionally adding headers to force the browser to download it
+func (c *Client4) DownloadFile(fileId string, download bool) ([]byte, *Response) {
+	if r, err := c.DoApiGet(c.GetFileRoute(fileId)+fmt.Sprintf("?download=%v", download), ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+
+		if data, err := ioutil.ReadAll(r.Body); err != nil {
+			return nil, BuildErrorResponse(r, NewAppError("DownloadFile", "model.client.read_file.app_error", nil, err.Error(), r.StatusCode))
+		} else {
+			return data, BuildResponse(r)
+		}
+	}
+}
+
+// GetFileThumbnail gets the bytes for a file by id.
+func (c *Client4) GetFileThumbnail(fileId string) ([]byte, *Response) {
+	if r, err := c.DoApiGet(c.GetFileRoute(fileId)+"/thumbnail", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+
+		if data, err := ioutil.ReadAll(r.Body); err != nil {
+			return nil, BuildErrorResponse(r, NewAppError("GetFileThumbnail", "model.client.read_file.app_error", nil, err.Error(), r.StatusCode))
+		} else {
+			return data, BuildResponse(r)
+		}
+	}
+}
+
+// DownloadFileThumbnail gets the bytes for a file by id, optionally adding headers to force the browser to download it.
+func (c *Client4) DownloadFileThumbnail(fileId string, download bool) ([]byte, *Response) {
+	if r, err := c.DoApiGet(c.GetFileRoute(fileId)+fmt.Sprintf("/thumbnail?download=%v", download), ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+
+		if data, err := ioutil.ReadAll(r.Body); err != nil {
+			return nil, BuildErrorResponse(r, NewAppError("DownloadFileThumbnail", "model.client.read_file.app_error", nil, err.Error(), r.StatusCode))
+		} else {
+			return data, BuildResponse(r)
+		}
+	}
+}
+
+// GetFileLink gets the public link of a file by id.
+func (c *Client4) GetFileLink(fileId string) (string, *Response) {
+	if r, err := c.DoApiGet(c.GetFileRoute(fileId)+"/link", ""); err != nil {
+		return "", BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+
+		return MapFromJson(r.Body)["link"], BuildResponse(r)
+	}
+}
+
+// GetUserFiles returns a page of the files uploaded by a user that match the filter, along with the count and size
+// of all of them. The filter's user is ignored. Page counting starts at 0.
+func (c *Client4) GetUserFiles(userId string, filter *FileInfoFilter, page int, perPage int) (*UserFiles, *Response) {
+	query := url.Values{}
+	if filter.TeamId != "" {
+		query.Set("team_id", filter.TeamId)
+	}
+	if filter.ChannelId != "" {
+		query.Set("channel_id", filter.ChannelId)
+	}
+	if filter.CreatedAfter != 0 {
+		query.Set("created_after", strconv.FormatInt(filter.CreatedAfter, 10))
+	}
+	if filter.CreatedBefore != 0 {
+		query.Set("created_before", strconv.FormatInt(filter.CreatedBefore, 10))
+	}
+	query.Set("page", strconv.Itoa(page))
+	query.Set("per_page", strconv.Itoa(perPage))
+
+	if r, err := c.DoApiGet(c.GetUserRoute(userId)+"/files?"+query.Encode(), ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return UserFilesFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// DeleteUserFile deletes a file uploaded by a user, removing it from the post that it's attached to. Files attached
+// to other users' posts can't be deleted.
+func (c *Client4) DeleteUserFile(userId string, fileId string) (bool, *Response) {
+	if r, err := c.DoApiDelete(c.GetUserRoute(userId) + "/files/" + fileId); err != nil {
+		return false, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CheckStatusOK(r), BuildResponse(r)
+	}
+}
+
+// GetTopFileStorageUsage returns a page of the users or teams, depending on usageType, that are using the most
+// storage, with the biggest first. Page counting starts at 0.
+func (c *Client4) GetTopFileStorageUsage(usageType string, page int, perPage int) ([]*FileStorageUsage, *Response) {
+	query := fmt.Sprintf("?type=%v&page=%v&per_page=%v", url.QueryEscape(usageType), page, perPage)
+	if r, err := c.DoApiGet(c.GetFilesRoute()+"/usage"+query, ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return FileStorageUsageListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// UpdateUserStorageExempt sets whether a user can upload files past the user and team storage quotas.
+func (c *Client4) UpdateUserStorageExempt(userId string, exempt bool) (bool, *Response) {
+	requestBody := map[string]interface{}{"exempt": exempt}
+	if r, err := c.DoApiPut(c.GetUserRoute(userId)+"/storage_exempt", StringInterfaceToJson(requestBody)); err != nil {
+		return false, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CheckStatusOK(r), BuildResponse(r)
+	}
+}
+
+// CreateFilePublicLink creates a public link to a file that can be limited to a number of downloads and expire.
+func (c *Client4) CreateFilePublicLink(fileId string, link *FilePublicLink) (*FilePublicLink, *Response) {
+	if r, err := c.DoApiPost(c.GetFileRoute(fileId)+"/links", link.ToJson()); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return FilePublicLinkFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetFilePublicLinks returns the current user's public links to files that can still be used.
+func (c *Client4) GetFilePublicLinks() ([]*FilePublicLink, *Response) {
+	if r, err := c.DoApiGet(c.GetFilesRoute()+"/links", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return FilePublicLinkListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetChannelFilePublicLinks returns the public links to files posted in a channel that can still be used.
+func (c *Client4) GetChannelFilePublicLinks(channelId string) ([]*FilePublicLink, *Response) {
+	if r, err := c.DoApiGet(c.GetChannelRoute(channelId)+"/files/public_links", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return FilePublicLinkListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// RevokeFilePublicLink stops a public link to a file from being used any further.
+func (c *Client4) RevokeFilePublicLink(linkId string) (bool, *Response) {
+	if r, err := c.DoApiDelete(c.GetFilesRoute() + "/links/" + linkId); err != nil {
+		return false, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CheckStatusOK(r), BuildResponse(r)
+	}
+}
+
+// GetFilePreview gets the bytes for a file by id.
+func (c *Client4) GetFilePreview(fileId string) ([]byte, *Response) {
+	if r, err := c.DoApiGet(c.GetFileRoute(fileId)+"/preview", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+
+		if data, err := ioutil.ReadAll(r.Body); err != nil {
+			return nil, BuildErrorResponse(r, NewAppError("GetFilePreview", "model.client.read_file.app_error", nil, err.Error(), r.StatusCode))
+		} else {
+			return data, BuildResponse(r)
+		}
+	}
+}
+
+// GetFileTextPreview gets the start of a text file, escaped so that it can be shown as HTML.
+func (c *Client4) GetFileTextPreview(fileId string) (*FileTextPreview, *Response) {
+	if r, err := c.DoApiGet(c.GetFileRoute(fileId)+"/preview", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return FileTextPreviewFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// DownloadFilePreview gets the bytes for a file by id.
+func (c *Client4) DownloadFilePreview(fileId string, download bool) ([]byte, *Response) {
+	if r, err := c.DoApiGet(c.GetFileRoute(fileId)+fmt.Sprintf("/preview?download=%v", download), ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+
+		if data, err := ioutil.ReadAll(r.Body); err != nil {
+			return nil, BuildErrorResponse(r, NewAppError("DownloadFilePreview", "model.client.read_file.app_error", nil, err.Error(), r.StatusCode))
+		} else {
+			return data, BuildResponse(r)
+		}
+	}
+}
+
+// GetFileInfo gets all the file info objects.
+func (c *Client4) GetFileInfo(fileId string) (*FileInfo, *Response) {
+	if r, err := c.DoApiGet(c.GetFileRoute(fileId)+"/info", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return FileInfoFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetFileInfosForPost gets all the file info objects attached to a post.
+func (c *Client4) GetFileInfosForPost(postId string, etag string) ([]*FileInfo, *Response) {
+	if r, err := c.DoApiGet(c.GetPostRoute(postId)+"/files/info", etag); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return FileInfosFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// General/System Section
+
+// GetPing will return ok if the running goRoutines are below the threshold and unhealthy for above.
+func (c *Client4) GetPing() (string, *Response) {
+	if r, err := c.DoApiGet(c.GetSystemRoute()+"/ping", ""); r != nil && r.StatusCode == 500 {
+		defer r.Body.Close()
+		return "unhealthy", BuildErrorResponse(r, err)
+	} else if r != nil && r.StatusCode == http.StatusServiceUnavailable {
+		defer r.Body.Close()
+		return STATUS_MAINTENANCE, BuildErrorResponse(r, err)
+	} else if err != nil {
+		return "", BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return MapFromJson(r.Body)["status"], BuildResponse(r)
+	}
+}
+
+// SetAnnouncement will show the announcement to all users. Must have manage_system permission.
+func (c *Client4) SetAnnouncement(announcement *Announcement) (bool, *Response) {
+	if r, err := c.DoApiPut(c.GetSystemRoute()+"/announcement", announcement.ToJson()); err != nil {
+		return false, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CheckStatusOK(r), BuildResponse(r)
+	}
+}
+
+// ClearAnnouncement will remove the announcement shown to all users. Must have manage_system permission.
+func (c *Client4) ClearAnnouncement() (bool, *Response) {
+	if r, err := c.DoApiDelete(c.GetSystemRoute() + "/announcement"); err != nil {
+		return false, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CheckStatusOK(r), BuildResponse(r)
+	}
+}
+
+// SetMaintenanceMode turns maintenance mode on or off for the whole cluster. While it's on, only system admins can use
+// the API, or everyone can only read if it's read-only. Must have manage_system permission.
+func (c *Client4) SetMaintenanceMode(mode *MaintenanceMode) (*MaintenanceMode, *Response) {
+	if r, err := c.DoApiPut(c.GetSystemRoute()+"/maintenance", mode.ToJson()); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return MaintenanceModeFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// StartDrain starts draining the server that handles the request so that it can be stopped without its users
+// noticing. It does nothing if the server is already draining. Must have manage_system permission.
+func (c *Client4) StartDrain() (*DrainStatus, *Response) {
+	if r, err := c.DoApiPost(c.GetSystemRoute()+"/drain", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return DrainStatusFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetDrainStatus returns how far along draining the server that handles the request is. Must have manage_system
+// permission.
+func (c *Client4) GetDrainStatus() (*DrainStatus, *Response) {
+	if r, err := c.DoApiGet(c.GetSystemRoute()+"/drain", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return DrainStatusFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetNotificationTraceStatus returns when tracing the user's notifications stops, if it's turned on. Must have
+// manage_system permission.
+func (c *Client4) GetNotificationTraceStatus(userId string) (*NotificationTraceStatus, *Response) {
+	if r, err := c.DoApiGet(c.GetUserRoute(userId)+"/notification_trace", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return NotificationTraceStatusFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// SetNotificationTrace turns tracing the user's notifications on for the given number of seconds, or off if it's 0.
+// Must have manage_system permission.
+func (c *Client4) SetNotificationTrace(userId string, ttlSeconds int64) (*NotificationTraceStatus, *Response) {
+	status := &NotificationTraceStatus{TTLSeconds: ttlSeconds}
+	if r, err := c.DoApiPut(c.GetUserRoute(userId)+"/notification_trace", status.ToJson()); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return NotificationTraceStatusFromJson(r.Body), BuildResponse(r)
 	}
 }
 
-// SearchPosts returns any posts with matching terms string.
-func (c *Client4) SearchPosts(teamId string, terms string, isOrSearch bool) (*PostList, *Response) {
-	requestBody := map[string]interface{}{"terms": terms, "is_or_search": isOrSearch}
-	if r, err := c.DoApiPost(c.GetTeamRoute(teamId)+"/posts/search", StringInterfaceToJson(requestBody)); err != nil {
+// GetNotificationTrace returns why the user was or wasn't notified about a post that was made while their notifications
+// were being traced. Must have manage_system permission.
+func (c *Client4) GetNotificationTrace(userId, postId string) (*NotificationTrace, *Response) {
+	if r, err := c.DoApiGet(c.GetUserRoute(userId)+"/notification_trace/"+postId, ""); err != nil {
 		return nil, BuildErrorResponse(r, err)
 	} else {
 		defer closeBody(r)
-		return PostListFromJson(r.Body), BuildResponse(r)
+		return NotificationTraceFromJson(r.Body), BuildResponse(r)
 	}
 }
 
-// DoPostAction performs a post action.
-func (c *Client4) DoPostAction(postId, actionId string) (bool, *Response) {
-	if r, err := c.DoApiPost(c.GetPostRoute(postId)+"/actions/"+actionId, ""); err != nil {
+// PublishUserTyping tells the other users in a channel that the user is typing, or only the users following or viewing the
+// thread if a parent post id is given.
+func (c *Client4) PublishUserTyping(userId string, typingRequest TypingRequest) (bool, *Response) {
+	if r, err := c.DoApiPost(c.GetUserRoute(userId)+"/typing", typingRequest.ToJson()); err != nil {
 		return false, BuildErrorResponse(r, err)
 	} else {
 		defer closeBody(r)
@@ -2084,172 +3445,135 @@ func (c *Client4) DoPostAction(postId, actionId string) (bool, *Response) {
 	}
 }
 
-// File Section
-
-// UploadFile will upload a file to a channel using a multipart request, to be later attached to a post.
-// This method is functionally equivalent to Client4.UploadFileAsRequestBody.
-func (c *Client4) UploadFile(data []byte, channelId string, filename string) (*FileUploadResponse, *Response) {
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-
-	if part, err := writer.CreateFormFile("files", filename); err != nil {
-		return nil, &Response{Error: NewAppError("UploadPostAttachment", "model.client.upload_post_attachment.file.app_error", nil, err.Error(), http.StatusBadRequest)}
-	} else if _, err = io.Copy(part, bytes.NewBuffer(data)); err != nil {
-		return nil, &Response{Error: NewAppError("UploadPostAttachment", "model.client.upload_post_attachment.file.app_error", nil, err.Error(), http.StatusBadRequest)}
-	}
-
-	if part, err := writer.CreateFormField("channel_id"); err != nil {
-		return nil, &Response{Error: NewAppError("UploadPostAttachment", "model.client.upload_post_attachment.channel_id.app_error", nil, err.Error(), http.StatusBadRequest)}
-	} else if _, err = io.Copy(part, strings.NewReader(channelId)); err != nil {
-		return nil, &Response{Error: NewAppError("UploadPostAttachment", "model.client.upload_post_attachment.channel_id.app_error", nil, err.Error(), http.StatusBadRequest)}
-	}
-
-	if err := writer.Close(); err != nil {
-		return nil, &Response{Error: NewAppError("UploadPostAttachment", "model.client.upload_post_attachment.writer.app_error", nil, err.Error(), http.StatusBadRequest)}
+// GetBlockedUsers returns the users that the user has blocked.
+func (c *Client4) GetBlockedUsers(userId string) ([]*User, *Response) {
+	if r, err := c.DoApiGet(c.GetUserRoute(userId)+"/blocked", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return UserListFromJson(r.Body), BuildResponse(r)
 	}
-
-	return c.DoUploadFile(c.GetFilesRoute(), body.Bytes(), writer.FormDataContentType())
-}
-
-// UploadFileAsRequestBody will upload a file to a channel as the body of a request, to be later attached
-// to a post. This method is functionally equivalent to Client4.UploadFile.
-func (c *Client4) UploadFileAsRequestBody(data []byte, channelId string, filename string) (*FileUploadResponse, *Response) {
-	return c.DoUploadFile(c.GetFilesRoute()+fmt.Sprintf("?channel_id=%v&filename=%v", url.QueryEscape(channelId), url.QueryEscape(filename)), data, http.DetectContentType(data))
 }
 
-// GetFile gets the bytes for a file by id.
-func (c *Client4) GetFile(fileId string) ([]byte, *Response) {
-	if r, err := c.DoApiGet(c.GetFileRoute(fileId), ""); err != nil {
-		return nil, BuildErrorResponse(r, err)
+// BlockUser stops another user from starting direct messages with the user or notifying them.
+func (c *Client4) BlockUser(userId, blockedUserId string) (bool, *Response) {
+	if r, err := c.DoApiPost(c.GetUserRoute(userId)+"/blocked/"+blockedUserId, ""); err != nil {
+		return false, BuildErrorResponse(r, err)
 	} else {
 		defer closeBody(r)
-
-		if data, err := ioutil.ReadAll(r.Body); err != nil {
-			return nil, BuildErrorResponse(r, NewAppError("GetFile", "model.client.read_file.app_error", nil, err.Error(), r.StatusCode))
-		} else {
-			return data, BuildResponse(r)
-		}
+		return CheckStatusOK(r), BuildResponse(r)
 	}
 }
 
-// DownloadFile gets the bytes for a file by id, optionally adding headers to force the browser to download it
-func (c *Client4) DownloadFile(fileId string, download bool) ([]byte, *Response) {
-	if r, err := c.DoApiGet(c.GetFileRoute(fileId)+fmt.Sprintf("?download=%v", download), ""); err != nil {
-		return nil, BuildErrorResponse(r, err)
+// UnblockUser removes another user from the user's block list.
+func (c *Client4) UnblockUser(userId, blockedUserId string) (bool, *Response) {
+	if r, err := c.DoApiDelete(c.GetUserRoute(userId) + "/blocked/" + blockedUserId); err != nil {
+		return false, BuildErrorResponse(r, err)
 	} else {
 		defer closeBody(r)
-
-		if data, err := ioutil.ReadAll(r.Body); err != nil {
-			return nil, BuildErrorResponse(r, NewAppError("DownloadFile", "model.client.read_file.app_error", nil, err.Error(), r.StatusCode))
-		} else {
-			return data, BuildResponse(r)
-		}
+		return CheckStatusOK(r), BuildResponse(r)
 	}
 }
 
-// GetFileThumbnail gets the bytes for a file by id.
-func (c *Client4) GetFileThumbnail(fileId string) ([]byte, *Response) {
-	if r, err := c.DoApiGet(c.GetFileRoute(fileId)+"/thumbnail", ""); err != nil {
+// GetRecentRequestTraces returns the traces of the most recent slow requests handled by the server that's connected
+// to, from newest to oldest. Must have manage_system permission.
+// GetAppErrorCatalog returns the errors that the server has registered machine codes for, along with the status that
+// each is returned with.
+func (c *Client4) GetAppErrorCatalog() ([]*AppErrorCatalogEntry, *Response) {
+	if r, err := c.DoApiGet(c.GetSystemRoute()+"/errors", ""); err != nil {
 		return nil, BuildErrorResponse(r, err)
 	} else {
 		defer closeBody(r)
-
-		if data, err := ioutil.ReadAll(r.Body); err != nil {
-			return nil, BuildErrorResponse(r, NewAppError("GetFileThumbnail", "model.client.read_file.app_error", nil, err.Error(), r.StatusCode))
-		} else {
-			return data, BuildResponse(r)
-		}
+		return AppErrorCatalogFromJson(r.Body), BuildResponse(r)
 	}
 }
 
-// DownloadFileThumbnail gets the bytes for a file by id, optionally adding headers to force the browser to download it.
-func (c *Client4) DownloadFileThumbnail(fileId string, download bool) ([]byte, *Response) {
-	if r, err := c.DoApiGet(c.GetFileRoute(fileId)+fmt.Sprintf("/thumbnail?download=%v", download), ""); err != nil {
+func (c *Client4) GetRecentRequestTraces() ([]*RequestTrace, *Response) {
+	if r, err := c.DoApiGet(c.GetSystemRoute()+"/traces", ""); err != nil {
 		return nil, BuildErrorResponse(r, err)
 	} else {
 		defer closeBody(r)
-
-		if data, err := ioutil.ReadAll(r.Body); err != nil {
-			return nil, BuildErrorResponse(r, NewAppError("DownloadFileThumbnail", "model.client.read_file.app_error", nil, err.Error(), r.StatusCode))
-		} else {
-			return data, BuildResponse(r)
-		}
+		return RequestTraceListFromJson(r.Body), BuildResponse(r)
 	}
 }
 
-// GetFileLink gets the public link of a file by id.
-func (c *Client4) GetFileLink(fileId string) (string, *Response) {
-	if r, err := c.DoApiGet(c.GetFileRoute(fileId)+"/link", ""); err != nil {
+// GetPostActionSigningSecret returns the secret that the requests to the post action integration at integrationURL
+// are signed with. Must have manage_system permission.
+func (c *Client4) GetPostActionSigningSecret(integrationURL string) (string, *Response) {
+	if r, err := c.DoApiGet(c.GetSystemRoute()+"/post_action_signing_secret?url="+url.QueryEscape(integrationURL), ""); err != nil {
 		return "", BuildErrorResponse(r, err)
 	} else {
 		defer closeBody(r)
-
-		return MapFromJson(r.Body)["link"], BuildResponse(r)
+		return MapFromJson(r.Body)["secret"], BuildResponse(r)
 	}
 }
 
-// GetFilePreview gets the bytes for a file by id.
-func (c *Client4) GetFilePreview(fileId string) ([]byte, *Response) {
-	if r, err := c.DoApiGet(c.GetFileRoute(fileId)+"/preview", ""); err != nil {
+// GenerateSupportPacket returns a zip file with the sanitized config, the last logMegabytes of the log and the state of
+// the server for diagnosing a problem with it. Must have manage_system permission.
+func (c *Client4) GenerateSupportPacket(logMegabytes int) ([]byte, *Response) {
+	if r, err := c.DoApiGet(c.GetSystemRoute()+"/support_packet?log_megabytes="+strconv.Itoa(logMegabytes), ""); err != nil {
 		return nil, BuildErrorResponse(r, err)
 	} else {
 		defer closeBody(r)
 
 		if data, err := ioutil.ReadAll(r.Body); err != nil {
-			return nil, BuildErrorResponse(r, NewAppError("GetFilePreview", "model.client.read_file.app_error", nil, err.Error(), r.StatusCode))
+			return nil, BuildErrorResponse(r, NewAppError("GenerateSupportPacket", "model.client.read_file.app_error", nil, err.Error(), r.StatusCode))
 		} else {
 			return data, BuildResponse(r)
 		}
 	}
 }
 
-// DownloadFilePreview gets the bytes for a file by id.
-func (c *Client4) DownloadFilePreview(fileId string, download bool) ([]byte, *Response) {
-	if r, err := c.DoApiGet(c.GetFileRoute(fileId)+fmt.Sprintf("/preview?download=%v", download), ""); err != nil {
+// GetFeatureFlagOverrides returns the feature flags that have been set through the API instead of the config. Must have
+// manage_system permission.
+func (c *Client4) GetFeatureFlagOverrides() (map[string]string, *Response) {
+	if r, err := c.DoApiGet(c.GetSystemRoute()+"/feature_flags", ""); err != nil {
 		return nil, BuildErrorResponse(r, err)
 	} else {
 		defer closeBody(r)
-
-		if data, err := ioutil.ReadAll(r.Body); err != nil {
-			return nil, BuildErrorResponse(r, NewAppError("DownloadFilePreview", "model.client.read_file.app_error", nil, err.Error(), r.StatusCode))
-		} else {
-			return data, BuildResponse(r)
-		}
+		return MapFromJson(r.Body), BuildResponse(r)
 	}
 }
 
-// GetFileInfo gets all the file info objects.
-func (c *Client4) GetFileInfo(fileId string) (*FileInfo, *Response) {
-	if r, err := c.DoApiGet(c.GetFileRoute(fileId)+"/info", ""); err != nil {
+// SetFeatureFlagOverrides replaces the feature flags that override the config for the whole cluster. Flags that aren't
+// included go back to the value in the config. Must have manage_system permission.
+func (c *Client4) SetFeatureFlagOverrides(overrides map[string]string) (map[string]string, *Response) {
+	if r, err := c.DoApiPut(c.GetSystemRoute()+"/feature_flags", MapToJson(overrides)); err != nil {
 		return nil, BuildErrorResponse(r, err)
 	} else {
 		defer closeBody(r)
-		return FileInfoFromJson(r.Body), BuildResponse(r)
+		return MapFromJson(r.Body), BuildResponse(r)
 	}
 }
 
-// GetFileInfosForPost gets all the file info objects attached to a post.
-func (c *Client4) GetFileInfosForPost(postId string, etag string) ([]*FileInfo, *Response) {
-	if r, err := c.DoApiGet(c.GetPostRoute(postId)+"/files/info", etag); err != nil {
+// GetBlockedDomains returns the domains that posts can't link to. Must have manage_system permission.
+func (c *Client4) GetBlockedDomains() ([]*BlockedDomain, *Response) {
+	if r, err := c.DoApiGet(c.GetSystemRoute()+"/link_blocklist", ""); err != nil {
 		return nil, BuildErrorResponse(r, err)
 	} else {
 		defer closeBody(r)
-		return FileInfosFromJson(r.Body), BuildResponse(r)
+		return BlockedDomainListFromJson(r.Body), BuildResponse(r)
 	}
 }
 
-// General/System Section
+// AddBlockedDomains adds domains to the link blocklist and returns the ones that weren't already on it. Domains that
+// start with *. block their subdomains. Must have manage_system permission.
+func (c *Client4) AddBlockedDomains(domains []string) ([]*BlockedDomain, *Response) {
+	if r, err := c.DoApiPost(c.GetSystemRoute()+"/link_blocklist", ArrayToJson(domains)); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return BlockedDomainListFromJson(r.Body), BuildResponse(r)
+	}
+}
 
-// GetPing will return ok if the running goRoutines are below the threshold and unhealthy for above.
-func (c *Client4) GetPing() (string, *Response) {
-	if r, err := c.DoApiGet(c.GetSystemRoute()+"/ping", ""); r != nil && r.StatusCode == 500 {
-		defer r.Body.Close()
-		return "unhealthy", BuildErrorResponse(r, err)
-	} else if err != nil {
-		return "", BuildErrorResponse(r, err)
+// RemoveBlockedDomains removes domains from the link blocklist. Must have manage_system permission.
+func (c *Client4) RemoveBlockedDomains(domains []string) (bool, *Response) {
+	if r, err := c.DoApiPost(c.GetSystemRoute()+"/link_blocklist/remove", ArrayToJson(domains)); err != nil {
+		return false, BuildErrorResponse(r, err)
 	} else {
 		defer closeBody(r)
-		return MapFromJson(r.Body)["status"], BuildResponse(r)
+		return CheckStatusOK(r), BuildResponse(r)
 	}
 }
 
@@ -2263,6 +3587,25 @@ func (c *Client4) TestEmail(config *Config) (bool, *Response) {
 	}
 }
 
+// SendEmailBounce notifies the server that an email couldn't be delivered, as the email provider would.
+func (c *Client4) SendEmailBounce(bounce *EmailBounce, secret string) (bool, *Response) {
+	rq, _ := http.NewRequest(http.MethodPost, c.ApiUrl+"/email/bounces", strings.NewReader(bounce.ToJson()))
+	rq.Header.Set(HEADER_EMAIL_BOUNCE_SECRET, secret)
+	rq.Close = true
+
+	if rp, err := c.HttpClient.Do(rq); err != nil || rp == nil {
+		return false, &Response{Error: NewAppError("/email/bounces", "model.client.connecting.app_error", nil, err.Error(), 0)}
+	} else {
+		defer closeBody(rp)
+
+		if rp.StatusCode >= 300 {
+			return false, BuildErrorResponse(rp, AppErrorFromJson(rp.Body))
+		} else {
+			return CheckStatusOK(rp), BuildResponse(rp)
+		}
+	}
+}
+
 // TestS3Connection will attempt to connect to the AWS S3.
 func (c *Client4) TestS3Connection(config *Config) (bool, *Response) {
 	if r, err := c.DoApiPost(c.GetTestS3Route(), config.ToJson()); err != nil {
@@ -2348,8 +3691,64 @@ func (c *Client4) InvalidateCaches() (bool, *Response) {
 }
 
 // UpdateConfig will update the server configuration.
+// UpdateConfig saves the config, which needs the ConfigVersion of the config that it was based on. If the config has
+// been saved since then, nothing is saved, and the conflict is returned as an error along with the current config so
+// that the changes can be made to it instead.
 func (c *Client4) UpdateConfig(config *Config) (*Config, *Response) {
-	if r, err := c.DoApiPut(c.GetConfigRoute(), config.ToJson()); err != nil {
+	r, err := c.HttpClient.Do(c.newApiRequest(http.MethodPut, c.ApiUrl+c.GetConfigRoute(), config.ToJson(), ""))
+	if err != nil || r == nil {
+		return nil, BuildErrorResponse(r, NewAppError(c.GetConfigRoute(), "model.client.connecting.app_error", nil, err.Error(), 0))
+	}
+	defer closeBody(r)
+
+	if r.StatusCode == http.StatusConflict {
+		if conflict := ConfigConflictFromJson(r.Body); conflict != nil {
+			return conflict.Config, BuildErrorResponse(r, &conflict.AppError)
+		}
+		return nil, BuildErrorResponse(r, NewAppError("UpdateConfig", "model.utils.decode_json.app_error", nil, "", r.StatusCode))
+	} else if r.StatusCode >= 300 {
+		return nil, BuildErrorResponse(r, AppErrorFromJson(r.Body))
+	}
+
+	return ConfigFromJson(r.Body), BuildResponse(r)
+}
+
+// PatchConfig applies the given settings onto the config, leaving every other setting as it is. The settings are
+// nested by section like they are in the config, such as {"TeamSettings": {"SiteName": "Mattermost"}}.
+func (c *Client4) PatchConfig(settings map[string]interface{}) (*Config, *Response) {
+	if r, err := c.DoApiRequest(http.MethodPatch, c.ApiUrl+c.GetConfigRoute(), StringInterfaceToJson(settings), ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ConfigFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetConfigHistory returns a page of the saved versions of the server configuration, from newest to oldest.
+func (c *Client4) GetConfigHistory(page int, perPage int) ([]*ConfigurationHistory, *Response) {
+	query := fmt.Sprintf("?page=%v&per_page=%v", page, perPage)
+	if r, err := c.DoApiGet(c.GetConfigRoute()+"/history"+query, ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ConfigurationHistoryListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetConfigHistoryDiff returns the settings that changed between the saved version of the server configuration
+// before the given one and the given one.
+func (c *Client4) GetConfigHistoryDiff(historyId string) ([]*ConfigChange, *Response) {
+	if r, err := c.DoApiGet(c.GetConfigRoute()+"/history/"+historyId+"/diff", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ConfigChangesFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// RestoreConfigHistory saves a previous version of the server configuration as the current one, and returns it.
+func (c *Client4) RestoreConfigHistory(historyId string) (*Config, *Response) {
+	if r, err := c.DoApiPost(c.GetConfigRoute()+"/history/"+historyId+"/restore", ""); err != nil {
 		return nil, BuildErrorResponse(r, err)
 	} else {
 		defer closeBody(r)
@@ -2418,6 +3817,68 @@ func (c *Client4) GetAnalyticsOld(name, teamId string) (AnalyticsRows, *Response
 	}
 }
 
+// GetTeamAnalyticsSeries returns the daily values of a metric for a team, one row per day with the day as
+// its name. The "from" and "to" days are in the YYYY-MM-DD format and are optional.
+func (c *Client4) GetTeamAnalyticsSeries(teamId, metric, from, to string) (AnalyticsRows, *Response) {
+	query := fmt.Sprintf("?metric=%v&from=%v&to=%v", metric, from, to)
+	if r, err := c.DoApiGet(c.GetAnalyticsRoute()+"/teams/"+teamId+query, ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return AnalyticsRowsFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetChannelAnalyticsSeries returns the daily values of a metric for a channel, one row per day with the day as
+// its name. The "from" and "to" days are in the YYYY-MM-DD format and are optional.
+func (c *Client4) GetChannelAnalyticsSeries(channelId, metric, from, to string) (AnalyticsRows, *Response) {
+	query := fmt.Sprintf("?metric=%v&from=%v&to=%v", metric, from, to)
+	if r, err := c.DoApiGet(c.GetAnalyticsRoute()+"/channels/"+channelId+query, ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return AnalyticsRowsFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetUserAnalytics returns a page of the activity of each user between the from and to days, inclusive, which
+// default to the 30 days up to yesterday.
+func (c *Client4) GetUserAnalytics(from, to string, page, perPage int) ([]*AnalyticsUserActivity, *Response) {
+	query := fmt.Sprintf("?from=%v&to=%v&page=%v&per_page=%v", from, to, page, perPage)
+	if r, err := c.DoApiGet(c.GetAnalyticsRoute()+"/users"+query, ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return AnalyticsUserActivityListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetUserAnalyticsCsv returns the activity of every user between the from and to days, inclusive, as a CSV file.
+func (c *Client4) GetUserAnalyticsCsv(from, to string) ([]byte, *Response) {
+	query := fmt.Sprintf("?from=%v&to=%v&format=csv", from, to)
+	if r, err := c.DoApiGet(c.GetAnalyticsRoute()+"/users"+query, ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		if data, err := ioutil.ReadAll(r.Body); err != nil {
+			return nil, BuildErrorResponse(r, NewAppError("GetUserAnalyticsCsv", "model.client.read_file.app_error", nil, err.Error(), r.StatusCode))
+		} else {
+			return data, BuildResponse(r)
+		}
+	}
+}
+
+// GetSeatReport returns the number of users taking up licensed seats along with the active member counts of every
+// team. Must be authenticated as a system admin.
+func (c *Client4) GetSeatReport() (*SeatReport, *Response) {
+	if r, err := c.DoApiGet(c.GetAnalyticsRoute()+"/seats", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return SeatReportFromJson(r.Body), BuildResponse(r)
+	}
+}
+
 // Webhooks Section
 
 // CreateIncomingWebhook creates an incoming webhook for a channel.
@@ -2555,6 +4016,16 @@ func (c *Client4) RegenOutgoingHookToken(hookId string) (*OutgoingWebhook, *Resp
 	}
 }
 
+// RegenOutgoingHookSigningSecret replaces the secret that an outgoing webhook's requests are signed with.
+func (c *Client4) RegenOutgoingHookSigningSecret(hookId string) (*OutgoingWebhook, *Response) {
+	if r, err := c.DoApiPost(c.GetOutgoingWebhookRoute(hookId)+"/regen_signing_secret", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return OutgoingWebhookFromJson(r.Body), BuildResponse(r)
+	}
+}
+
 // DeleteOutgoingWebhook delete the outgoing webhook on the system requested by Hook Id.
 func (c *Client4) DeleteOutgoingWebhook(hookId string) (bool, *Response) {
 	if r, err := c.DoApiDelete(c.GetOutgoingWebhookRoute(hookId)); err != nil {
@@ -2578,6 +4049,21 @@ func (c *Client4) GetPreferences(userId string) (Preferences, *Response) {
 	}
 }
 
+// GetPreferencesPage returns a page of the user's preferences, optionally only those in the given category.
+func (c *Client4) GetPreferencesPage(userId string, category string, page int, perPage int) (Preferences, *Response) {
+	query := fmt.Sprintf("?page=%v&per_page=%v", page, perPage)
+	if category != "" {
+		query += "&category=" + url.QueryEscape(category)
+	}
+	if r, err := c.DoApiGet(c.GetPreferencesRoute(userId)+query, ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		preferences, _ := PreferencesFromJson(r.Body)
+		defer closeBody(r)
+		return preferences, BuildResponse(r)
+	}
+}
+
 // UpdatePreferences saves the user's preferences.
 func (c *Client4) UpdatePreferences(userId string, preferences *Preferences) (bool, *Response) {
 	if r, err := c.DoApiPut(c.GetPreferencesRoute(userId), preferences.ToJson()); err != nil {
@@ -2598,26 +4084,58 @@ func (c *Client4) DeletePreferences(userId string, preferences *Preferences) (bo
 	}
 }
 
-// GetPreferencesByCategory returns the user's preferences from the provided category string.
-func (c *Client4) GetPreferencesByCategory(userId string, category string) (Preferences, *Response) {
-	url := fmt.Sprintf(c.GetPreferencesRoute(userId)+"/%s", category)
-	if r, err := c.DoApiGet(url, ""); err != nil {
+// GetPreferencesByCategory returns the user's preferences from the provided category string.
+func (c *Client4) GetPreferencesByCategory(userId string, category string) (Preferences, *Response) {
+	url := fmt.Sprintf(c.GetPreferencesRoute(userId)+"/%s", category)
+	if r, err := c.DoApiGet(url, ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		preferences, _ := PreferencesFromJson(r.Body)
+		defer closeBody(r)
+		return preferences, BuildResponse(r)
+	}
+}
+
+// GetPreferenceByCategoryAndName returns the user's preferences from the provided category and preference name string.
+func (c *Client4) GetPreferenceByCategoryAndName(userId string, category string, preferenceName string) (*Preference, *Response) {
+	url := fmt.Sprintf(c.GetPreferencesRoute(userId)+"/%s/name/%v", category, preferenceName)
+	if r, err := c.DoApiGet(url, ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return PreferenceFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// Saved Searches Section
+
+// CreateSavedSearch saves a search for the user so that it can be shown as a shortcut.
+func (c *Client4) CreateSavedSearch(userId string, search *SavedSearch) (*SavedSearch, *Response) {
+	if r, err := c.DoApiPost(c.GetSavedSearchesRoute(userId), search.ToJson()); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return SavedSearchFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetSavedSearches returns the user's saved searches, oldest first.
+func (c *Client4) GetSavedSearches(userId string) ([]*SavedSearch, *Response) {
+	if r, err := c.DoApiGet(c.GetSavedSearchesRoute(userId), ""); err != nil {
 		return nil, BuildErrorResponse(r, err)
 	} else {
-		preferences, _ := PreferencesFromJson(r.Body)
 		defer closeBody(r)
-		return preferences, BuildResponse(r)
+		return SavedSearchListFromJson(r.Body), BuildResponse(r)
 	}
 }
 
-// GetPreferenceByCategoryAndName returns the user's preferences from the provided category and preference name string.
-func (c *Client4) GetPreferenceByCategoryAndName(userId string, category string, preferenceName string) (*Preference, *Response) {
-	url := fmt.Sprintf(c.GetPreferencesRoute(userId)+"/%s/name/%v", category, preferenceName)
-	if r, err := c.DoApiGet(url, ""); err != nil {
-		return nil, BuildErrorResponse(r, err)
+// DeleteSavedSearch deletes one of the user's saved searches.
+func (c *Client4) DeleteSavedSearch(userId string, savedSearchId string) (bool, *Response) {
+	if r, err := c.DoApiDelete(c.GetSavedSearchesRoute(userId) + "/" + savedSearchId); err != nil {
+		return false, BuildErrorResponse(r, err)
 	} else {
 		defer closeBody(r)
-		return PreferenceFromJson(r.Body), BuildResponse(r)
+		return CheckStatusOK(r), BuildResponse(r)
 	}
 }
 
@@ -3038,6 +4556,26 @@ func (c *Client4) GetOAuthAccessToken(data url.Values) (*AccessResponse, *Respon
 	}
 }
 
+// IntrospectOAuthToken gets whether an OAuth access or refresh token is active using the credentials of an OAuth app.
+func (c *Client4) IntrospectOAuthToken(clientId, clientSecret, token string) (*IntrospectionResponse, *Response) {
+	data := url.Values{"token": []string{token}}
+	rq, _ := http.NewRequest(http.MethodPost, c.Url+"/oauth/introspect", strings.NewReader(data.Encode()))
+	rq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rq.SetBasicAuth(clientId, clientSecret)
+	rq.Close = true
+
+	if rp, err := c.HttpClient.Do(rq); err != nil || rp == nil {
+		return nil, &Response{StatusCode: http.StatusForbidden, Error: NewAppError(c.Url+"/oauth/introspect", "model.client.connecting.app_error", nil, err.Error(), 403)}
+	} else {
+		defer closeBody(rp)
+		if rp.StatusCode >= 300 {
+			return nil, BuildErrorResponse(rp, AppErrorFromJson(rp.Body))
+		} else {
+			return IntrospectionResponseFromJson(rp.Body), BuildResponse(rp)
+		}
+	}
+}
+
 // Elasticsearch Section
 
 // TestElasticsearch will attempt to connect to the configured Elasticsearch server and return OK if configured
@@ -3170,6 +4708,16 @@ func (c *Client4) RegenCommandToken(commandId string) (string, *Response) {
 	}
 }
 
+// RegenCommandSigningSecret replaces the secret that a slash command's requests are signed with, returning the new one.
+func (c *Client4) RegenCommandSigningSecret(commandId string) (string, *Response) {
+	if r, err := c.DoApiPut(c.GetCommandRoute(commandId)+"/regen_signing_secret", ""); err != nil {
+		return "", BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return MapFromJson(r.Body)["signing_secret"], BuildResponse(r)
+	}
+}
+
 // Status Section
 
 // GetUserStatus returns a user based on the provided user id string.
@@ -3253,6 +4801,28 @@ func (c *Client4) GetEmojiList(page, perPage int) ([]*Emoji, *Response) {
 	}
 }
 
+// GetEmojiStats returns a page of custom emoji ordered by how much they've been used.
+func (c *Client4) GetEmojiStats(page, perPage int) ([]*EmojiStats, *Response) {
+	query := fmt.Sprintf("?page=%v&per_page=%v", page, perPage)
+	if r, err := c.DoApiGet(c.GetEmojisRoute()+"/stats"+query, ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return EmojiStatsListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// DeleteUnusedEmoji deletes the custom emoji which are more than the given number of days old and haven't been
+// used in that time, and returns them.
+func (c *Client4) DeleteUnusedEmoji(days int) ([]*Emoji, *Response) {
+	if r, err := c.DoApiDelete(c.GetEmojisRoute() + fmt.Sprintf("/unused?days=%v", days)); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return EmojiListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
 // GetSortedEmojiList returns a page of custom emoji on the system sorted based on the sort
 // parameter, blank for no sorting and "name" to sort by emoji names.
 func (c *Client4) GetSortedEmojiList(page, perPage int, sort string) ([]*Emoji, *Response) {
@@ -3484,6 +5054,330 @@ func (c *Client4) PatchRole(roleId string, patch *RolePatch) (*Role, *Response)
 	}
 }
 
+// Custom Group Section
+
+// CreateCustomGroup creates a group that can be mentioned to notify all of its members at once.
+func (c *Client4) CreateCustomGroup(group *CustomGroup) (*CustomGroup, *Response) {
+	if r, err := c.DoApiPost(c.GetCustomGroupsRoute(), group.ToJson()); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CustomGroupFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetCustomGroups returns a page of custom groups sorted by name.
+func (c *Client4) GetCustomGroups(page, perPage int) ([]*CustomGroup, *Response) {
+	query := fmt.Sprintf("?page=%v&per_page=%v", page, perPage)
+	if r, err := c.DoApiGet(c.GetCustomGroupsRoute()+query, ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CustomGroupListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetCustomGroup returns a single custom group.
+func (c *Client4) GetCustomGroup(groupId string) (*CustomGroup, *Response) {
+	if r, err := c.DoApiGet(c.GetCustomGroupRoute(groupId), ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CustomGroupFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// PatchCustomGroup partially updates a custom group. Any missing fields are left unchanged.
+func (c *Client4) PatchCustomGroup(groupId string, patch *CustomGroupPatch) (*CustomGroup, *Response) {
+	if r, err := c.DoApiPut(c.GetCustomGroupRoute(groupId)+"/patch", patch.ToJson()); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CustomGroupFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// DeleteCustomGroup deletes a custom group along with its members.
+func (c *Client4) DeleteCustomGroup(groupId string) (bool, *Response) {
+	if r, err := c.DoApiDelete(c.GetCustomGroupRoute(groupId)); err != nil {
+		return false, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CheckStatusOK(r), BuildResponse(r)
+	}
+}
+
+// GetCustomGroupMembers returns the members of a custom group.
+func (c *Client4) GetCustomGroupMembers(groupId string) ([]*GroupMember, *Response) {
+	if r, err := c.DoApiGet(c.GetCustomGroupRoute(groupId)+"/members", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return GroupMemberListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// AddCustomGroupMembers adds users to a custom group.
+func (c *Client4) AddCustomGroupMembers(groupId string, userIds []string) ([]*GroupMember, *Response) {
+	if r, err := c.DoApiPost(c.GetCustomGroupRoute(groupId)+"/members", ArrayToJson(userIds)); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return GroupMemberListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// RemoveCustomGroupMember removes a user from a custom group.
+func (c *Client4) RemoveCustomGroupMember(groupId, userId string) (bool, *Response) {
+	if r, err := c.DoApiDelete(c.GetCustomGroupRoute(groupId) + "/members/" + userId); err != nil {
+		return false, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CheckStatusOK(r), BuildResponse(r)
+	}
+}
+
+// User Attributes Section
+
+// CreateUserAttributeField adds a custom attribute to every user's profile.
+func (c *Client4) CreateUserAttributeField(field *UserAttributeField) (*UserAttributeField, *Response) {
+	if r, err := c.DoApiPost(c.GetUserAttributeFieldsRoute(), field.ToJson()); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return UserAttributeFieldFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetUserAttributeFields returns every custom user attribute in the order that they're shown on profiles.
+func (c *Client4) GetUserAttributeFields() ([]*UserAttributeField, *Response) {
+	if r, err := c.DoApiGet(c.GetUserAttributeFieldsRoute(), ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return UserAttributeFieldListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetUserAttributeField returns a single custom user attribute.
+func (c *Client4) GetUserAttributeField(fieldId string) (*UserAttributeField, *Response) {
+	if r, err := c.DoApiGet(c.GetUserAttributeFieldRoute(fieldId), ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return UserAttributeFieldFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// PatchUserAttributeField partially updates a custom user attribute. Any values that users have for it that no
+// longer fit are removed.
+func (c *Client4) PatchUserAttributeField(fieldId string, patch *UserAttributeFieldPatch) (*UserAttributeField, *Response) {
+	if r, err := c.DoApiPut(c.GetUserAttributeFieldRoute(fieldId)+"/patch", patch.ToJson()); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return UserAttributeFieldFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// DeleteUserAttributeField deletes a custom user attribute along with every user's value for it.
+func (c *Client4) DeleteUserAttributeField(fieldId string) (bool, *Response) {
+	if r, err := c.DoApiDelete(c.GetUserAttributeFieldRoute(fieldId)); err != nil {
+		return false, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CheckStatusOK(r), BuildResponse(r)
+	}
+}
+
+// GetUserAttributes returns the user's custom attribute values keyed by field id.
+func (c *Client4) GetUserAttributes(userId string) (map[string]string, *Response) {
+	if r, err := c.DoApiGet(c.GetUserRoute(userId)+"/attributes", ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return MapFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// UpdateUserAttributes sets the user's custom attribute values keyed by field id. Values that are empty are cleared.
+func (c *Client4) UpdateUserAttributes(userId string, values map[string]string) (map[string]string, *Response) {
+	if r, err := c.DoApiPut(c.GetUserRoute(userId)+"/attributes", MapToJson(values)); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return MapFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// AutocompleteUsersInTeamWithAttributes returns the users in a team based on search term whose custom attributes
+// have the given values, keyed by field id.
+func (c *Client4) AutocompleteUsersInTeamWithAttributes(teamId string, username string, attributes map[string]string) (*UserAutocomplete, *Response) {
+	query := fmt.Sprintf("?in_team=%v&name=%v", teamId, username)
+	for fieldId, value := range attributes {
+		query += fmt.Sprintf("&attribute_%v=%v", fieldId, url.QueryEscape(value))
+	}
+
+	if r, err := c.DoApiGet(c.GetUsersRoute()+"/autocomplete"+query, ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return UserAutocompleteFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// Provisioning Token Section
+
+// CreateProvisioningToken creates a token that an identity provider can use to provision users and groups over SCIM.
+// The returned token is the only time that its secret is available.
+func (c *Client4) CreateProvisioningToken(description string) (*ProvisioningToken, *Response) {
+	token := &ProvisioningToken{Description: description}
+	if r, err := c.DoApiPost(c.GetProvisioningTokensRoute(), token.ToJson()); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ProvisioningTokenFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetProvisioningTokens returns a page of provisioning tokens without their secrets.
+func (c *Client4) GetProvisioningTokens(page, perPage int) ([]*ProvisioningToken, *Response) {
+	query := fmt.Sprintf("?page=%v&per_page=%v", page, perPage)
+	if r, err := c.DoApiGet(c.GetProvisioningTokensRoute()+query, ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ProvisioningTokenListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// RevokeProvisioningToken deletes a provisioning token so that it can no longer be used.
+func (c *Client4) RevokeProvisioningToken(tokenId string) (bool, *Response) {
+	if r, err := c.DoApiDelete(c.GetProvisioningTokenRoute(tokenId)); err != nil {
+		return false, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CheckStatusOK(r), BuildResponse(r)
+	}
+}
+
+// Team Templates Section
+
+// CreateTeamFromTemplate creates the team in the template along with its channels, default channels, admins and
+// incoming webhooks. With update, a team with the same name that already exists is changed to match the template.
+func (c *Client4) CreateTeamFromTemplate(template *TeamTemplate, update bool) (*Team, *Response) {
+	query := fmt.Sprintf("?update=%v", update)
+	if r, err := c.DoApiPost(c.GetTeamsRoute()+"/from_template"+query, template.ToJson()); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return TeamFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// CreateTeamFromSavedTemplate creates a team from a saved template, with the name and display name of the given
+// team if it has them.
+func (c *Client4) CreateTeamFromSavedTemplate(templateId string, team *Team, update bool) (*Team, *Response) {
+	query := fmt.Sprintf("?template_id=%v&update=%v", templateId, update)
+	template := &TeamTemplate{Team: team}
+	if r, err := c.DoApiPost(c.GetTeamsRoute()+"/from_template"+query, template.ToJson()); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return TeamFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// SaveTeamTemplate saves a team template on the server so that it can be listed and used again.
+func (c *Client4) SaveTeamTemplate(template *TeamTemplate) (*TeamTemplate, *Response) {
+	if r, err := c.DoApiPost(c.GetTeamTemplatesRoute(), template.ToJson()); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return TeamTemplateFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+func (c *Client4) UpdateTeamTemplate(template *TeamTemplate) (*TeamTemplate, *Response) {
+	if r, err := c.DoApiPut(c.GetTeamTemplateRoute(template.Id), template.ToJson()); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return TeamTemplateFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+func (c *Client4) GetTeamTemplate(templateId string) (*TeamTemplate, *Response) {
+	if r, err := c.DoApiGet(c.GetTeamTemplateRoute(templateId), ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return TeamTemplateFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetTeamTemplates returns a page of the saved team templates, sorted by name.
+func (c *Client4) GetTeamTemplates(page, perPage int) ([]*TeamTemplate, *Response) {
+	query := fmt.Sprintf("?page=%v&per_page=%v", page, perPage)
+	if r, err := c.DoApiGet(c.GetTeamTemplatesRoute()+query, ""); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return TeamTemplateListFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+func (c *Client4) DeleteTeamTemplate(templateId string) (bool, *Response) {
+	if r, err := c.DoApiDelete(c.GetTeamTemplateRoute(templateId)); err != nil {
+		return false, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CheckStatusOK(r), BuildResponse(r)
+	}
+}
+
+// Terms of Service Section
+
+// CreateTermsOfService creates a new version of the terms of service, which every user has to accept again.
+func (c *Client4) CreateTermsOfService(text string) (*TermsOfService, *Response) {
+	if r, err := c.DoApiPost(c.GetTermsOfServiceRoute(), MapToJson(map[string]string{"text": text})); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return TermsOfServiceFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetTermsOfService returns the latest version of the terms of service.
+func (c *Client4) GetTermsOfService(etag string) (*TermsOfService, *Response) {
+	if r, err := c.DoApiGet(c.GetTermsOfServiceRoute(), etag); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return TermsOfServiceFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// GetUserTermsOfService returns the version of the terms of service that the user last accepted.
+func (c *Client4) GetUserTermsOfService(userId string, etag string) (*UserTermsOfService, *Response) {
+	if r, err := c.DoApiGet(c.GetUserTermsOfServiceRoute(userId), etag); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return UserTermsOfServiceFromJson(r.Body), BuildResponse(r)
+	}
+}
+
+// AcceptTermsOfService records that the user accepted the given version of the terms of service.
+func (c *Client4) AcceptTermsOfService(userId string, termsOfServiceId string) (bool, *Response) {
+	if r, err := c.DoApiPost(c.GetUserTermsOfServiceRoute(userId), MapToJson(map[string]string{"terms_of_service_id": termsOfServiceId})); err != nil {
+		return false, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return CheckStatusOK(r), BuildResponse(r)
+	}
+}
+
 // Plugin Section
 
 // UploadPlugin takes an io.Reader stream pointing to the contents of a .tar.gz plugin.
@@ -3523,6 +5417,18 @@ func (c *Client4) UploadPlugin(file io.Reader) (*Manifest, *Response) {
 	}
 }
 
+// InstallPluginFromURL asks the server to download a .tar.gz plugin, verify its SHA-256 checksum and install it,
+// activating it too if request.Activate is set.
+// WARNING: PLUGINS ARE STILL EXPERIMENTAL. THIS FUNCTION IS SUBJECT TO CHANGE.
+func (c *Client4) InstallPluginFromURL(request *InstallPluginFromURLRequest) (*Manifest, *Response) {
+	if r, err := c.DoApiPost(c.GetPluginsRoute()+"/install_from_url", request.ToJson()); err != nil {
+		return nil, BuildErrorResponse(r, err)
+	} else {
+		defer closeBody(r)
+		return ManifestFromJson(r.Body), BuildResponse(r)
+	}
+}
+
 // GetPlugins will return a list of plugin manifests for currently active plugins.
 // WARNING: PLUGINS ARE STILL EXPERIMENTAL. THIS FUNCTION IS SUBJECT TO CHANGE.
 func (c *Client4) GetPlugins() (*PluginsResponse, *Response) {
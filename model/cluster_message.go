@@ -21,7 +21,15 @@ const (
 	CLUSTER_EVENT_INVALIDATE_CACHE_FOR_CHANNEL                      = "inv_channel"
 	CLUSTER_EVENT_INVALIDATE_CACHE_FOR_USER                         = "inv_user"
 	CLUSTER_EVENT_CLEAR_SESSION_CACHE_FOR_USER                      = "clear_session_user"
+	CLUSTER_EVENT_CLEAR_SESSION_CACHE_FOR_ALL_USERS                 = "clear_session_all_users"
 	CLUSTER_EVENT_INVALIDATE_CACHE_FOR_ROLES                        = "inv_roles"
+	CLUSTER_EVENT_INVALIDATE_CACHE                                  = "inv_cache"
+	CLUSTER_EVENT_UPDATE_MAINTENANCE_MODE                           = "update_maintenance_mode"
+	CLUSTER_EVENT_INVALIDATE_CACHE_FOR_LINK_BLOCKLIST               = "inv_link_blocklist"
+	CLUSTER_EVENT_UPDATE_FEATURE_FLAGS                              = "update_feature_flags"
+	CLUSTER_EVENT_UPDATE_NOTIFICATION_TRACE                         = "update_notification_trace"
+	CLUSTER_EVENT_CLEAR_SESSION_CACHE_FOR_SESSIONS                  = "clear_session_ids"
+	CLUSTER_EVENT_INVALIDATE_CACHE_FOR_OPEN_GRAPH                   = "inv_open_graph"
 
 	CLUSTER_SEND_BEST_EFFORT = "best_effort"
 	CLUSTER_SEND_RELIABLE    = "reliable"
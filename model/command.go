@@ -35,6 +35,13 @@ type Command struct {
 	DisplayName      string `json:"display_name"`
 	Description      string `json:"description"`
 	URL              string `json:"url"`
+
+	// SigningSecret, if set, signs the requests sent to URL with SigningAlgorithm. See SignIntegrationRequest.
+	SigningSecret    string `json:"signing_secret"`
+	SigningAlgorithm string `json:"signing_algorithm"`
+
+	// DisableLegacyToken leaves Token out of the requests, for receivers that verify their signatures instead.
+	DisableLegacyToken bool `json:"disable_legacy_token"`
 }
 
 func (o *Command) ToJson() string {
@@ -109,6 +116,14 @@ func (o *Command) IsValid() *AppError {
 		return NewAppError("Command.IsValid", "model.command.is_valid.description.app_error", nil, "", http.StatusBadRequest)
 	}
 
+	if !IsValidIntegrationSigningAlgorithm(o.SigningAlgorithm) {
+		return NewAppError("Command.IsValid", "model.command.is_valid.signing_algorithm.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if o.DisableLegacyToken && o.SigningSecret == "" {
+		return NewAppError("Command.IsValid", "model.command.is_valid.disable_legacy_token.app_error", nil, "", http.StatusBadRequest)
+	}
+
 	return nil
 }
 
@@ -121,6 +136,10 @@ func (o *Command) PreSave() {
 		o.Token = NewId()
 	}
 
+	if o.SigningSecret == "" {
+		o.SigningSecret = NewRandomString(INTEGRATION_SIGNING_SECRET_LENGTH)
+	}
+
 	o.CreateAt = GetMillis()
 	o.UpdateAt = o.CreateAt
 }
@@ -131,6 +150,7 @@ func (o *Command) PreUpdate() {
 
 func (o *Command) Sanitize() {
 	o.Token = ""
+	o.SigningSecret = ""
 	o.CreatorId = ""
 	o.Method = ""
 	o.URL = ""
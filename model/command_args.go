@@ -20,6 +20,7 @@ type CommandArgs struct {
 	SiteURL   string               `json:"-"`
 	T         goi18n.TranslateFunc `json:"-"`
 	Session   Session              `json:"-"`
+	RequestId string               `json:"-"`
 }
 
 func (o *CommandArgs) ToJson() string {
@@ -161,6 +161,26 @@ func TestCommandIsValid(t *testing.T) {
 	if err := o.IsValid(); err != nil {
 		t.Fatal(err)
 	}
+
+	o.SigningAlgorithm = "md5"
+	if err := o.IsValid(); err == nil {
+		t.Fatal("should be invalid")
+	}
+
+	o.SigningAlgorithm = INTEGRATION_SIGNING_ALGORITHM_SHA256
+	if err := o.IsValid(); err != nil {
+		t.Fatal(err)
+	}
+
+	o.DisableLegacyToken = true
+	if err := o.IsValid(); err == nil {
+		t.Fatal("should be invalid without a signing secret")
+	}
+
+	o.SigningSecret = NewRandomString(INTEGRATION_SIGNING_SECRET_LENGTH)
+	if err := o.IsValid(); err != nil {
+		t.Fatal(err)
+	}
 }
 
 func TestCommandPreSave(t *testing.T) {
@@ -0,0 +1,189 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	COMPLIANCE_EXPORT_FORMAT_CSV      = "csv"
+	COMPLIANCE_EXPORT_FORMAT_ACTIANCE = "actiance"
+
+	COMPLIANCE_EXPORT_POST_STATUS_EDITED           = "edited"
+	COMPLIANCE_EXPORT_POST_STATUS_PREVIOUS_VERSION = "previous_version"
+	COMPLIANCE_EXPORT_POST_STATUS_DELETED          = "deleted"
+)
+
+// ComplianceExportPost is a post being exported for compliance along with the team, channel and user that it
+// belongs to. Deleted posts and the versions of posts that were replaced by edits are exported too.
+type ComplianceExportPost struct {
+	TeamName        string
+	TeamDisplayName string
+
+	ChannelId          string
+	ChannelName        string
+	ChannelDisplayName string
+	ChannelType        string
+
+	UserId       string
+	UserUsername string
+	UserEmail    string
+
+	PostId         string
+	PostCreateAt   int64
+	PostUpdateAt   int64
+	PostDeleteAt   int64
+	PostRootId     string
+	PostOriginalId string
+	PostMessage    string
+	PostType       string
+	PostProps      string
+	PostFileIds    StringArray
+
+	// PostIsEdited is whether there are earlier versions of the post.
+	PostIsEdited bool
+}
+
+// ComplianceExportFile is a file attached to an exported post. Files which have been deleted since are
+// exported with only their id.
+type ComplianceExportFile struct {
+	Id        string
+	Name      string
+	Path      string
+	IsDeleted bool
+}
+
+// Status returns whether the post was deleted, is a version of a post from before an edit or has been edited.
+// It's blank for other posts.
+func (p *ComplianceExportPost) Status() string {
+	if p.PostOriginalId != "" {
+		return COMPLIANCE_EXPORT_POST_STATUS_PREVIOUS_VERSION
+	}
+
+	if p.PostDeleteAt > 0 {
+		return COMPLIANCE_EXPORT_POST_STATUS_DELETED
+	}
+
+	if p.PostIsEdited {
+		return COMPLIANCE_EXPORT_POST_STATUS_EDITED
+	}
+
+	return ""
+}
+
+func ComplianceExportPostHeader() []string {
+	return []string{
+		"TeamName",
+		"TeamDisplayName",
+
+		"ChannelId",
+		"ChannelName",
+		"ChannelDisplayName",
+		"ChannelType",
+
+		"UserId",
+		"UserUsername",
+		"UserEmail",
+
+		"PostId",
+		"PostCreateAt",
+		"PostUpdateAt",
+		"PostDeleteAt",
+		"PostRootId",
+		"PostOriginalId",
+		"PostStatus",
+		"PostMessage",
+		"PostType",
+		"PostProps",
+		"PostFiles",
+	}
+}
+
+// Row returns the post as a row of a CSV export, with its times in location.
+func (p *ComplianceExportPost) Row(files []*ComplianceExportFile, location *time.Location) []string {
+	postUpdateAt := ""
+	if p.PostUpdateAt != p.PostCreateAt {
+		postUpdateAt = complianceExportTime(p.PostUpdateAt, location)
+	}
+
+	postDeleteAt := ""
+	if p.PostDeleteAt > 0 {
+		postDeleteAt = complianceExportTime(p.PostDeleteAt, location)
+	}
+
+	fileNames := make([]string, 0, len(files))
+	for _, file := range files {
+		fileNames = append(fileNames, file.Description())
+	}
+
+	return []string{
+		cleanComplianceStrings(p.TeamName),
+		cleanComplianceStrings(p.TeamDisplayName),
+
+		p.ChannelId,
+		cleanComplianceStrings(p.ChannelName),
+		cleanComplianceStrings(p.ChannelDisplayName),
+		p.ChannelType,
+
+		p.UserId,
+		cleanComplianceStrings(p.UserUsername),
+		cleanComplianceStrings(p.UserEmail),
+
+		p.PostId,
+		complianceExportTime(p.PostCreateAt, location),
+		postUpdateAt,
+		postDeleteAt,
+		p.PostRootId,
+		p.PostOriginalId,
+		p.Status(),
+		cleanComplianceStrings(p.PostMessage),
+		p.PostType,
+		p.PostProps,
+		cleanComplianceStrings(strings.Join(fileNames, "; ")),
+	}
+}
+
+// Description returns how the file is referred to in an export.
+func (f *ComplianceExportFile) Description() string {
+	if f.IsDeleted {
+		return "(deleted file " + f.Id + ")"
+	}
+
+	return f.Name + " (" + f.Path + ")"
+}
+
+func ComplianceExportChannelMemberHeader() []string {
+	return []string{
+		"ChannelId",
+		"ChannelName",
+		"UserId",
+		"UserUsername",
+		"UserEmail",
+		"JoinTime",
+		"LeaveTime",
+	}
+}
+
+func ComplianceExportChannelMemberRow(channelName string, member *ChannelMemberHistoryResult, location *time.Location) []string {
+	leaveTime := ""
+	if member.LeaveTime != nil {
+		leaveTime = complianceExportTime(*member.LeaveTime, location)
+	}
+
+	return []string{
+		member.ChannelId,
+		cleanComplianceStrings(channelName),
+		member.UserId,
+		cleanComplianceStrings(member.Username),
+		cleanComplianceStrings(member.UserEmail),
+		complianceExportTime(member.JoinTime, location),
+		leaveTime,
+	}
+}
+
+func complianceExportTime(millis int64, location *time.Location) string {
+	return time.Unix(0, millis*int64(time.Millisecond)).In(location).Format(time.RFC3339)
+}
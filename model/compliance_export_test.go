@@ -0,0 +1,105 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComplianceExportPostStatus(t *testing.T) {
+	assert.Equal(t, "", (&ComplianceExportPost{}).Status())
+	assert.Equal(t, COMPLIANCE_EXPORT_POST_STATUS_EDITED, (&ComplianceExportPost{PostIsEdited: true}).Status())
+	assert.Equal(t, COMPLIANCE_EXPORT_POST_STATUS_DELETED, (&ComplianceExportPost{PostDeleteAt: 1, PostIsEdited: true}).Status())
+	assert.Equal(t, COMPLIANCE_EXPORT_POST_STATUS_PREVIOUS_VERSION, (&ComplianceExportPost{PostDeleteAt: 1, PostOriginalId: NewId()}).Status())
+}
+
+func TestComplianceExportPostRow(t *testing.T) {
+	post := &ComplianceExportPost{
+		TeamName:           "team",
+		ChannelId:          NewId(),
+		ChannelName:        "channel",
+		ChannelDisplayName: "Channel",
+		ChannelType:        CHANNEL_OPEN,
+		UserId:             NewId(),
+		UserUsername:       "user",
+		UserEmail:          "user@example.com",
+		PostId:             NewId(),
+		PostCreateAt:       1514764800000,
+		PostUpdateAt:       1514764800000,
+		PostMessage:        "=1+1",
+		PostFileIds:        StringArray{"file1", "file2"},
+	}
+	files := []*ComplianceExportFile{
+		{Id: "file1", Name: "a.png", Path: "data/a.png"},
+		{Id: "file2", IsDeleted: true},
+	}
+
+	header := ComplianceExportPostHeader()
+	row := post.Row(files, time.UTC)
+	require.Len(t, row, len(header))
+
+	column := func(name string) string {
+		for i, h := range header {
+			if h == name {
+				return row[i]
+			}
+		}
+		t.Fatalf("missing column %v", name)
+		return ""
+	}
+
+	assert.Equal(t, post.PostId, column("PostId"))
+	assert.Equal(t, "2018-01-01T00:00:00Z", column("PostCreateAt"))
+	assert.Equal(t, "", column("PostUpdateAt"), "unedited posts have no update time")
+	assert.Equal(t, "", column("PostDeleteAt"))
+	assert.Equal(t, "", column("PostStatus"))
+	assert.Equal(t, "'=1+1", column("PostMessage"), "formulas are escaped")
+	assert.Equal(t, "a.png (data/a.png); (deleted file file2)", column("PostFiles"))
+
+	post.PostUpdateAt = 1514764860000
+	post.PostDeleteAt = 1514764860000
+	row = post.Row(files, time.UTC)
+	assert.Equal(t, "2018-01-01T00:01:00Z", column("PostUpdateAt"))
+	assert.Equal(t, "2018-01-01T00:01:00Z", column("PostDeleteAt"))
+	assert.Equal(t, COMPLIANCE_EXPORT_POST_STATUS_DELETED, column("PostStatus"))
+}
+
+func TestComplianceExportChannelMemberRow(t *testing.T) {
+	leaveTime := int64(1514764860000)
+	member := &ChannelMemberHistoryResult{ChannelId: NewId(), UserId: NewId(), JoinTime: 1514764800000, LeaveTime: &leaveTime, Username: "user", UserEmail: "user@example.com"}
+
+	row := ComplianceExportChannelMemberRow("channel", member, time.UTC)
+	require.Len(t, row, len(ComplianceExportChannelMemberHeader()))
+	assert.Equal(t, []string{member.ChannelId, "channel", member.UserId, "user", "user@example.com", "2018-01-01T00:00:00Z", "2018-01-01T00:01:00Z"}, row)
+
+	member.LeaveTime = nil
+	assert.Equal(t, "", ComplianceExportChannelMemberRow("channel", member, time.UTC)[6])
+}
+
+func TestComplianceExportTimezone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.Nil(t, err)
+	newYork, err := time.LoadLocation("America/New_York")
+	require.Nil(t, err)
+
+	post := &ComplianceExportPost{PostCreateAt: 1514764800000, PostUpdateAt: 1514764800000}
+	header := ComplianceExportPostHeader()
+	createAt := -1
+	for i, name := range header {
+		if name == "PostCreateAt" {
+			createAt = i
+		}
+	}
+	require.NotEqual(t, -1, createAt)
+
+	assert.Equal(t, "2018-01-01T09:00:00+09:00", post.Row(nil, tokyo)[createAt])
+	assert.Equal(t, "2017-12-31T19:00:00-05:00", post.Row(nil, newYork)[createAt])
+
+	member := &ChannelMemberHistoryResult{JoinTime: 1514764800000}
+	assert.Equal(t, "2018-01-01T09:00:00+09:00", ComplianceExportChannelMemberRow("channel", member, tokyo)[5])
+}
@@ -6,8 +6,11 @@ package model
 import (
 	"encoding/json"
 	"io"
+	"mime"
+	"net"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strings"
 	"time"
 )
@@ -43,6 +46,10 @@ const (
 	DIRECT_MESSAGE_ANY  = "any"
 	DIRECT_MESSAGE_TEAM = "team"
 
+	GROUP_CHANNEL_MANAGE_MEMBERS_NONE    = "none"
+	GROUP_CHANNEL_MANAGE_MEMBERS_CREATOR = "creator"
+	GROUP_CHANNEL_MANAGE_MEMBERS_ANY     = "any"
+
 	SHOW_USERNAME          = "username"
 	SHOW_NICKNAME_FULLNAME = "nickname_full_name"
 	SHOW_FULLNAME          = "full_name"
@@ -70,9 +77,14 @@ const (
 	GROUP_UNREAD_CHANNELS_DEFAULT_ON  = "default_on"
 	GROUP_UNREAD_CHANNELS_DEFAULT_OFF = "default_off"
 
+	SCIM_GROUP_MAPPING_CUSTOM_GROUPS = "custom_groups"
+	SCIM_GROUP_MAPPING_TEAMS         = "teams"
+
 	EMAIL_BATCHING_BUFFER_SIZE = 256
 	EMAIL_BATCHING_INTERVAL    = 30
 
+	EMAIL_SETTINGS_DEFAULT_QUEUED_EMAILS_PER_MINUTE = 120
+
 	EMAIL_NOTIFICATION_CONTENTS_FULL    = "full"
 	EMAIL_NOTIFICATION_CONTENTS_GENERIC = "generic"
 
@@ -86,14 +98,58 @@ const (
 	SERVICE_SETTINGS_DEFAULT_MAX_LOGIN_ATTEMPTS = 10
 	SERVICE_SETTINGS_DEFAULT_ALLOW_CORS_FROM    = ""
 	SERVICE_SETTINGS_DEFAULT_LISTEN_AND_ADDRESS = ":8065"
+	SERVICE_SETTINGS_DEFAULT_TIMEZONE           = "UTC"
+
+	SERVICE_SETTINGS_DEFAULT_WEBSOCKET_HUB_REBALANCE_INTERVAL_SECONDS = 60
+	SERVICE_SETTINGS_DEFAULT_MAX_PRESENCE_SUBSCRIPTIONS               = 1000
+	SERVICE_SETTINGS_DEFAULT_WEBSOCKET_SLOW_CLIENT_MAX_DROPS          = 1
+	SERVICE_SETTINGS_DEFAULT_WEBSOCKET_PING_INTERVAL_SECONDS          = 60
+	SERVICE_SETTINGS_DEFAULT_WEBSOCKET_PONG_TIMEOUT_SECONDS           = 40
+	SERVICE_SETTINGS_DEFAULT_WEBSOCKET_MAX_MISSED_PONGS               = 1
+	SERVICE_SETTINGS_DEFAULT_WEBSOCKET_DRAIN_WINDOW_SECONDS           = 60
+
+	SERVICE_SETTINGS_DEFAULT_CONFIG_WATCH_DEBOUNCE_MILLISECONDS = 500
+
+	SERVICE_SETTINGS_DEFAULT_POST_PIPELINE_QUEUE_SIZE = 10000
+
+	SERVICE_SETTINGS_DEFAULT_POST_IDEMPOTENCY_WINDOW_SECONDS = 5 * 60
+	SERVICE_SETTINGS_DEFAULT_POST_IDEMPOTENCY_CACHE_SIZE     = 10000
+	SERVICE_SETTINGS_DEFAULT_CHANNEL_DELETION_GRACE_DAYS     = 7
+
+	SERVICE_SETTINGS_DEFAULT_WRITE_BEHIND_INTERVAL_MILLISECONDS = 1000
+
+	SERVICE_SETTINGS_DEFAULT_SHORT_PERMALINK_EXPIRY_DAYS = 30
+
+	SERVICE_SETTINGS_DEFAULT_CONFIG_HISTORY_MAX_VERSIONS = 100
+
+	SERVICE_SETTINGS_DEFAULT_REQUEST_TRACING_SAMPLE_RATE                 = 0.01
+	SERVICE_SETTINGS_DEFAULT_REQUEST_TRACING_SLOW_THRESHOLD_MILLISECONDS = 1000
+
+	// Reverse proxies are usually on the same machine or a private network
+	SERVICE_SETTINGS_DEFAULT_TRUSTED_PROXY_IP_HEADER = HEADER_FORWARDED
+	SERVICE_SETTINGS_DEFAULT_TRUSTED_PROXY_CIDRS     = "127.0.0.0/8 ::1/128 10.0.0.0/8 172.16.0.0/12 192.168.0.0/16 fc00::/7"
+
+	SERVICE_SETTINGS_DEFAULT_UNIQUE_EMOJI_REACTION_LIMIT_PER_POST = 50
+	SERVICE_SETTINGS_MAX_UNIQUE_EMOJI_REACTION_LIMIT_PER_POST     = 500
+	SERVICE_SETTINGS_DEFAULT_MAX_POST_PROPS_SIZE                  = POST_PROPS_MAX_USER_RUNES
+
+	SERVICE_SETTINGS_DEFAULT_SECRET_SCANNING_TIME_BUDGET_MILLISECONDS = 50
+	SERVICE_SETTINGS_DEFAULT_CHANNEL_FEED_POST_COUNT                  = 20
+
+	SERVICE_SETTINGS_DEFAULT_LOCAL_MODE_SOCKET_LOCATION = "/var/tmp/mattermost_local.socket"
 
 	TEAM_SETTINGS_DEFAULT_MAX_USERS_PER_TEAM       = 50
 	TEAM_SETTINGS_DEFAULT_CUSTOM_BRAND_TEXT        = ""
 	TEAM_SETTINGS_DEFAULT_CUSTOM_DESCRIPTION_TEXT  = ""
 	TEAM_SETTINGS_DEFAULT_USER_STATUS_AWAY_TIMEOUT = 300
+	TEAM_SETTINGS_DEFAULT_MAX_CHANNEL_HEADER       = 1024
+	TEAM_SETTINGS_DEFAULT_MAX_CHANNEL_PURPOSE      = 250
 
 	SQL_SETTINGS_DEFAULT_DATA_SOURCE = "mmuser:mostest@tcp(dockerhost:3306)/mattermost_test?charset=utf8mb4,utf8&readTimeout=30s&writeTimeout=30s"
 
+	SQL_SETTINGS_DEFAULT_HEALTH_CHECK_INTERVAL_SECONDS  = 10
+	SQL_SETTINGS_DEFAULT_HEALTH_CHECK_FAILURE_THRESHOLD = 3
+
 	EMAIL_SETTINGS_DEFAULT_FEEDBACK_ORGANIZATION = ""
 
 	SUPPORT_SETTINGS_DEFAULT_TERMS_OF_SERVICE_LINK = "https://about.mattermost.com/default-terms/"
@@ -128,9 +184,12 @@ const (
 	WEBRTC_SETTINGS_DEFAULT_TURN_URI = ""
 
 	ANALYTICS_SETTINGS_DEFAULT_MAX_USERS_FOR_STATISTICS = 2500
+	ANALYTICS_SETTINGS_DEFAULT_DAILY_RETENTION_DAYS     = 365
+	ANALYTICS_SETTINGS_DEFAULT_MINIMUM_USER_ACTIVITY    = 0
 
 	ANNOUNCEMENT_SETTINGS_DEFAULT_BANNER_COLOR      = "#f2a93b"
 	ANNOUNCEMENT_SETTINGS_DEFAULT_BANNER_TEXT_COLOR = "#333333"
+	ANNOUNCEMENT_SETTINGS_DEFAULT_WELCOME_MESSAGE   = "Welcome to {{.TeamName}}, {{.UserFirstName}}! Here are a few things to get you started:\n\n1. Add a profile picture in **Account Settings** so that your teammates can recognize you.\n2. Browse the channels of the team from **More...** in the sidebar and join the ones you're interested in.\n3. Download the desktop and mobile apps so that you don't miss any messages."
 
 	TEAM_SETTINGS_DEFAULT_TEAM_TEXT = "default"
 
@@ -150,11 +209,58 @@ const (
 	DATA_RETENTION_SETTINGS_DEFAULT_FILE_RETENTION_DAYS     = 365
 	DATA_RETENTION_SETTINGS_DEFAULT_DELETION_JOB_START_TIME = "02:00"
 
-	PLUGIN_SETTINGS_DEFAULT_DIRECTORY        = "./plugins"
-	PLUGIN_SETTINGS_DEFAULT_CLIENT_DIRECTORY = "./client/plugins"
+	BASIC_RETENTION_SETTINGS_DEFAULT_DELETION_JOB_START_TIME = "02:00"
+	BASIC_RETENTION_SETTINGS_DEFAULT_BATCH_SIZE              = 3000
+
+	DEACTIVATION_SETTINGS_DEFAULT_IDLE_DAYS         = 90
+	DEACTIVATION_SETTINGS_DEFAULT_GRACE_PERIOD_DAYS = 7
+	DEACTIVATION_SETTINGS_DEFAULT_EXEMPT_ROLES      = SYSTEM_ADMIN_ROLE_ID
+	DEACTIVATION_SETTINGS_DEFAULT_JOB_START_TIME    = "03:00"
+
+	CHANNEL_ARCHIVING_SETTINGS_DEFAULT_INACTIVE_DAYS  = 180
+	CHANNEL_ARCHIVING_SETTINGS_DEFAULT_WARNING_DAYS   = 7
+	CHANNEL_ARCHIVING_SETTINGS_DEFAULT_JOB_START_TIME = "04:00"
+
+	CAPTCHA_PROVIDER_RECAPTCHA = "recaptcha"
+	CAPTCHA_PROVIDER_PLUGIN    = "plugin"
+
+	CAPTCHA_SETTINGS_DEFAULT_VERIFY_URL              = "https://www.google.com/recaptcha/api/siteverify"
+	CAPTCHA_SETTINGS_DEFAULT_VERIFY_TIMEOUT_SECONDS  = 5
+	CAPTCHA_SETTINGS_DEFAULT_LOGIN_FAILURES_PER_IP   = 10
+	CAPTCHA_SETTINGS_DEFAULT_LOGIN_FAILURES_PER_USER = 3
+	CAPTCHA_SETTINGS_DEFAULT_FAILURE_WINDOW_MINUTES  = 60
+
+	PLUGIN_SETTINGS_DEFAULT_DIRECTORY                        = "./plugins"
+	PLUGIN_SETTINGS_DEFAULT_CLIENT_DIRECTORY                 = "./client/plugins"
+	PLUGIN_SETTINGS_DEFAULT_INSTALL_FROM_URL_MAX_FILE_SIZE   = 50 * 1024 * 1024
+	PLUGIN_SETTINGS_DEFAULT_INSTALL_FROM_URL_TIMEOUT_SECONDS = 60
 
 	TIMEZONE_SETTINGS_DEFAULT_SUPPORTED_TIMEZONES_PATH = "timezones.json"
 
+	IMAGE_PROXY_TYPE_LOCAL      = "local"
+	IMAGE_PROXY_TYPE_ATMOS_CAMO = "atmos/camo"
+
+	SESSION_ANOMALY_DETECTION_MODE_LAX    = "lax"
+	SESSION_ANOMALY_DETECTION_MODE_STRICT = "strict"
+
+	WEBSOCKET_SLOW_CLIENT_POLICY_DROP       = "drop"
+	WEBSOCKET_SLOW_CLIENT_POLICY_DISCONNECT = "disconnect"
+
+	SECRET_SCANNING_MODE_OFF   = "off"
+	SECRET_SCANNING_MODE_WARN  = "warn"
+	SECRET_SCANNING_MODE_BLOCK = "block"
+	SECRET_SCANNING_MODE_ALERT = "alert"
+
+	WORD_FILTER_MODE_OFF   = "off"
+	WORD_FILTER_MODE_MASK  = "mask"
+	WORD_FILTER_MODE_BLOCK = "block"
+	WORD_FILTER_MODE_FLAG  = "flag"
+
+	LINK_BLOCKLIST_MODE_OFF   = "off"
+	LINK_BLOCKLIST_MODE_WARN  = "warn"
+	LINK_BLOCKLIST_MODE_STRIP = "strip"
+	LINK_BLOCKLIST_MODE_BLOCK = "block"
+
 	COMPLIANCE_EXPORT_TYPE_ACTIANCE    = "actiance"
 	COMPLIANCE_EXPORT_TYPE_GLOBALRELAY = "globalrelay"
 	GLOBALRELAY_CUSTOMER_TYPE_A9       = "A9"
@@ -185,30 +291,86 @@ type ServiceSettings struct {
 	EnablePostUsernameOverride                        bool
 	EnablePostIconOverride                            bool
 	EnableLinkPreviews                                *bool
+	EnablePermalinkPreviews                           *bool
 	EnableTesting                                     bool
 	EnableDeveloper                                   *bool
 	EnableSecurityFixAlert                            *bool
 	EnableInsecureOutgoingConnections                 *bool
 	AllowedUntrustedInternalConnections               *string
+	PostActionSigningSecret                           *string
 	EnableMultifactorAuthentication                   *bool
 	EnforceMultifactorAuthentication                  *bool
 	EnableUserAccessTokens                            *bool
+	EnableUserImpersonation                           *bool
+	EnableUserBlocking                                *bool
+	ImpersonationSessionLengthInMinutes               *int
+	EnableLocalMode                                   *bool
+	LocalModeSocketLocation                           *string
+	EnablePreflightChecks                             *bool
+	AbortStartupOnPreflightFailure                    *bool
+	SkipMigrations                                    *bool
+	ConfigWatchDebounceMilliseconds                   *int
+	ReliableWebsocketEvents                           *bool
 	AllowCorsFrom                                     *string
+	CorsAllowedHeaders                                *string
+	CorsExposedHeaders                                *string
+	CorsAllowCredentials                              *bool
+	CorsMaxAge                                        *int
 	AllowCookiesForSubdomains                         *bool
 	SessionLengthWebInDays                            *int
 	SessionLengthMobileInDays                         *int
 	SessionLengthSSOInDays                            *int
 	SessionCacheInMinutes                             *int
 	SessionIdleTimeoutInMinutes                       *int
+	SessionCleanupIntervalMinutes                     *int
+	SessionCleanupBatchSize                           *int
+	SessionCleanupRetentionDays                       *int
+	AuditRetentionDays                                *int
+	AuditRetentionMaxRows                             *int
+	JobRetentionDays                                  *int
+	JobRetentionMaxRows                               *int
+	TableTrimIntervalMinutes                          *int
+	TableTrimBatchSize                                *int
+	SessionAnomalyDetection                           *bool
+	SessionAnomalyDetectionMode                       *string
+	SessionAnomalyAllowedCIDRs                        *string
 	WebsocketSecurePort                               *int
 	WebsocketPort                                     *int
+	WebsocketHubs                                     *int
+	WebsocketHubRebalanceIntervalSeconds              *int
+	WebsocketSlowClientPolicy                         *string
+	WebsocketSlowClientMaxDrops                       *int
+	WebsocketPingIntervalSeconds                      *int
+	WebsocketPongTimeoutSeconds                       *int
+	WebsocketMaxMissedPongs                           *int
+	WebsocketDrainWindowSeconds                       *int
+	EnablePresenceSubscriptions                       *bool
+	MaxPresenceSubscriptionsPerConnection             *int
 	WebserverMode                                     *string
+	PrecompressStaticFiles                            *bool
+	DefaultTimezone                                   *string
 	EnableCustomEmoji                                 *bool
 	EnableEmojiPicker                                 *bool
 	RestrictCustomEmojiCreation                       *string
 	RestrictPostDelete                                *string
 	AllowEditPost                                     *string
 	PostEditTimeLimit                                 *int
+	PostEditTimeLimitByRole                           map[string]int
+	PostDeleteTimeLimit                               *int
+	PostDeleteTimeLimitByRole                         map[string]int
+	RestrictPostEditHistoryToAdmins                   *bool
+	UniqueEmojiReactionLimitPerPost                   *int
+	MaxPostPropsSize                                  *int
+	SecretScanningMode                                *string
+	SecretScanningRules                               map[string]string
+	SecretScanningAlertChannelId                      *string
+	SecretScanningTimeBudgetMilliseconds              *int
+	WordFilterMode                                    *string
+	FilteredWords                                     []string
+	WordFilterExemptSystemAdmins                      *bool
+	LinkBlocklistMode                                 *string
+	EnableChannelFeeds                                *bool
+	ChannelFeedPostCount                              *int
 	TimeBetweenUserTypingUpdatesMilliseconds          *int64
 	EnablePostSearch                                  *bool
 	EnableUserTypingMessages                          *bool
@@ -220,11 +382,28 @@ type ServiceSettings struct {
 	EnablePreviewFeatures                             *bool
 	EnableTutorial                                    *bool
 	ExperimentalEnableDefaultChannelLeaveJoinMessages *bool
+	EnableJoinLeaveMessagesByDefault                  *bool
 	ExperimentalGroupUnreadChannels                   *string
 	ImageProxyType                                    *string
 	ImageProxyURL                                     *string
 	ImageProxyOptions                                 *string
 	EnableAPITeamDeletion                             *bool
+	EnableAPIChannelDeletion                          *bool
+	ChannelDeletionGracePeriodDays                    *int
+	PostPipelineWorkers                               *int
+	PostPipelineQueueSize                             *int
+	PostIdempotencyWindowSeconds                      *int
+	PostIdempotencyCacheSize                          *int
+	WriteBehindIntervalMilliseconds                   *int
+	TrustedProxyIPHeader                              *string
+	TrustedProxyCIDRs                                 *string
+	DisabledAPIEndpoints                              []string
+	EnableShortPermalinks                             *bool
+	ShortPermalinkExpiryDays                          *int
+	ConfigHistoryMaxVersions                          *int
+	EnableRequestTracing                              *bool
+	RequestTracingSampleRate                          *float64
+	RequestTracingSlowThresholdMilliseconds           *int
 }
 
 func (s *ServiceSettings) SetDefaults() {
@@ -248,6 +427,10 @@ func (s *ServiceSettings) SetDefaults() {
 		s.EnableLinkPreviews = NewBool(false)
 	}
 
+	if s.EnablePermalinkPreviews == nil {
+		s.EnablePermalinkPreviews = NewBool(true)
+	}
+
 	if s.EnableDeveloper == nil {
 		s.EnableDeveloper = NewBool(false)
 	}
@@ -264,6 +447,10 @@ func (s *ServiceSettings) SetDefaults() {
 		s.AllowedUntrustedInternalConnections = NewString("")
 	}
 
+	if s.PostActionSigningSecret == nil || len(*s.PostActionSigningSecret) == 0 {
+		s.PostActionSigningSecret = NewString(NewRandomString(32))
+	}
+
 	if s.EnableMultifactorAuthentication == nil {
 		s.EnableMultifactorAuthentication = NewBool(false)
 	}
@@ -276,6 +463,47 @@ func (s *ServiceSettings) SetDefaults() {
 		s.EnableUserAccessTokens = NewBool(false)
 	}
 
+	if s.EnableUserBlocking == nil {
+		s.EnableUserBlocking = NewBool(true)
+	}
+
+	if s.EnableLocalMode == nil {
+		s.EnableLocalMode = NewBool(false)
+	}
+
+	if s.LocalModeSocketLocation == nil {
+		s.LocalModeSocketLocation = NewString(SERVICE_SETTINGS_DEFAULT_LOCAL_MODE_SOCKET_LOCATION)
+	}
+
+	if s.EnablePreflightChecks == nil {
+		s.EnablePreflightChecks = NewBool(true)
+	}
+
+	if s.AbortStartupOnPreflightFailure == nil {
+		s.AbortStartupOnPreflightFailure = NewBool(true)
+	}
+
+	// The database is migrated by the db migrate command instead of by the server when this is set
+	if s.SkipMigrations == nil {
+		s.SkipMigrations = NewBool(false)
+	}
+
+	if s.ConfigWatchDebounceMilliseconds == nil {
+		s.ConfigWatchDebounceMilliseconds = NewInt(SERVICE_SETTINGS_DEFAULT_CONFIG_WATCH_DEBOUNCE_MILLISECONDS)
+	}
+
+	if s.ReliableWebsocketEvents == nil {
+		s.ReliableWebsocketEvents = NewBool(false)
+	}
+
+	if s.EnableUserImpersonation == nil {
+		s.EnableUserImpersonation = NewBool(false)
+	}
+
+	if s.ImpersonationSessionLengthInMinutes == nil {
+		s.ImpersonationSessionLengthInMinutes = NewInt(60)
+	}
+
 	if s.GoroutineHealthThreshold == nil {
 		s.GoroutineHealthThreshold = NewInt(-1)
 	}
@@ -368,6 +596,54 @@ func (s *ServiceSettings) SetDefaults() {
 		s.SessionIdleTimeoutInMinutes = NewInt(0)
 	}
 
+	if s.SessionCleanupIntervalMinutes == nil {
+		s.SessionCleanupIntervalMinutes = NewInt(24 * 60)
+	}
+
+	if s.SessionCleanupBatchSize == nil {
+		s.SessionCleanupBatchSize = NewInt(1000)
+	}
+
+	if s.SessionCleanupRetentionDays == nil {
+		s.SessionCleanupRetentionDays = NewInt(0)
+	}
+
+	if s.AuditRetentionDays == nil {
+		s.AuditRetentionDays = NewInt(0)
+	}
+
+	if s.AuditRetentionMaxRows == nil {
+		s.AuditRetentionMaxRows = NewInt(0)
+	}
+
+	if s.JobRetentionDays == nil {
+		s.JobRetentionDays = NewInt(0)
+	}
+
+	if s.JobRetentionMaxRows == nil {
+		s.JobRetentionMaxRows = NewInt(0)
+	}
+
+	if s.TableTrimIntervalMinutes == nil {
+		s.TableTrimIntervalMinutes = NewInt(24 * 60)
+	}
+
+	if s.TableTrimBatchSize == nil {
+		s.TableTrimBatchSize = NewInt(1000)
+	}
+
+	if s.SessionAnomalyDetection == nil {
+		s.SessionAnomalyDetection = NewBool(false)
+	}
+
+	if s.SessionAnomalyDetectionMode == nil {
+		s.SessionAnomalyDetectionMode = NewString(SESSION_ANOMALY_DETECTION_MODE_LAX)
+	}
+
+	if s.SessionAnomalyAllowedCIDRs == nil {
+		s.SessionAnomalyAllowedCIDRs = NewString("")
+	}
+
 	if s.EnableCommands == nil {
 		s.EnableCommands = NewBool(false)
 	}
@@ -384,10 +660,67 @@ func (s *ServiceSettings) SetDefaults() {
 		s.WebsocketSecurePort = NewInt(443)
 	}
 
+	// 0 uses twice as many hubs as there are CPUs
+	if s.WebsocketHubs == nil {
+		s.WebsocketHubs = NewInt(0)
+	}
+
+	if s.WebsocketHubRebalanceIntervalSeconds == nil {
+		s.WebsocketHubRebalanceIntervalSeconds = NewInt(SERVICE_SETTINGS_DEFAULT_WEBSOCKET_HUB_REBALANCE_INTERVAL_SECONDS)
+	}
+
+	if s.WebsocketSlowClientPolicy == nil {
+		s.WebsocketSlowClientPolicy = NewString(WEBSOCKET_SLOW_CLIENT_POLICY_DISCONNECT)
+	}
+
+	if s.WebsocketSlowClientMaxDrops == nil {
+		s.WebsocketSlowClientMaxDrops = NewInt(SERVICE_SETTINGS_DEFAULT_WEBSOCKET_SLOW_CLIENT_MAX_DROPS)
+	}
+
+	if s.WebsocketPingIntervalSeconds == nil {
+		s.WebsocketPingIntervalSeconds = NewInt(SERVICE_SETTINGS_DEFAULT_WEBSOCKET_PING_INTERVAL_SECONDS)
+	}
+
+	if s.WebsocketPongTimeoutSeconds == nil {
+		s.WebsocketPongTimeoutSeconds = NewInt(SERVICE_SETTINGS_DEFAULT_WEBSOCKET_PONG_TIMEOUT_SECONDS)
+	}
+
+	if s.WebsocketMaxMissedPongs == nil {
+		s.WebsocketMaxMissedPongs = NewInt(SERVICE_SETTINGS_DEFAULT_WEBSOCKET_MAX_MISSED_PONGS)
+	}
+
+	if s.WebsocketDrainWindowSeconds == nil {
+		s.WebsocketDrainWindowSeconds = NewInt(SERVICE_SETTINGS_DEFAULT_WEBSOCKET_DRAIN_WINDOW_SECONDS)
+	}
+
+	if s.EnablePresenceSubscriptions == nil {
+		s.EnablePresenceSubscriptions = NewBool(false)
+	}
+
+	if s.MaxPresenceSubscriptionsPerConnection == nil {
+		s.MaxPresenceSubscriptionsPerConnection = NewInt(SERVICE_SETTINGS_DEFAULT_MAX_PRESENCE_SUBSCRIPTIONS)
+	}
+
 	if s.AllowCorsFrom == nil {
 		s.AllowCorsFrom = NewString(SERVICE_SETTINGS_DEFAULT_ALLOW_CORS_FROM)
 	}
 
+	if s.CorsAllowedHeaders == nil {
+		s.CorsAllowedHeaders = NewString("")
+	}
+
+	if s.CorsExposedHeaders == nil {
+		s.CorsExposedHeaders = NewString("")
+	}
+
+	if s.CorsAllowCredentials == nil {
+		s.CorsAllowCredentials = NewBool(false)
+	}
+
+	if s.CorsMaxAge == nil {
+		s.CorsMaxAge = NewInt(0)
+	}
+
 	if s.AllowCookiesForSubdomains == nil {
 		s.AllowCookiesForSubdomains = NewBool(false)
 	}
@@ -398,6 +731,14 @@ func (s *ServiceSettings) SetDefaults() {
 		*s.WebserverMode = "gzip"
 	}
 
+	if s.PrecompressStaticFiles == nil {
+		s.PrecompressStaticFiles = NewBool(false)
+	}
+
+	if s.DefaultTimezone == nil {
+		s.DefaultTimezone = NewString(SERVICE_SETTINGS_DEFAULT_TIMEZONE)
+	}
+
 	if s.EnableCustomEmoji == nil {
 		s.EnableCustomEmoji = NewBool(false)
 	}
@@ -426,6 +767,73 @@ func (s *ServiceSettings) SetDefaults() {
 		s.PostEditTimeLimit = NewInt(-1)
 	}
 
+	if s.PostEditTimeLimitByRole == nil {
+		s.PostEditTimeLimitByRole = make(map[string]int)
+	}
+
+	if s.PostDeleteTimeLimit == nil {
+		s.PostDeleteTimeLimit = NewInt(-1)
+	}
+
+	if s.PostDeleteTimeLimitByRole == nil {
+		s.PostDeleteTimeLimitByRole = make(map[string]int)
+	}
+
+	if s.RestrictPostEditHistoryToAdmins == nil {
+		s.RestrictPostEditHistoryToAdmins = NewBool(false)
+	}
+
+	if s.UniqueEmojiReactionLimitPerPost == nil {
+		s.UniqueEmojiReactionLimitPerPost = NewInt(SERVICE_SETTINGS_DEFAULT_UNIQUE_EMOJI_REACTION_LIMIT_PER_POST)
+	}
+
+	if s.MaxPostPropsSize == nil {
+		s.MaxPostPropsSize = NewInt(SERVICE_SETTINGS_DEFAULT_MAX_POST_PROPS_SIZE)
+	}
+
+	if s.SecretScanningMode == nil {
+		s.SecretScanningMode = NewString(SECRET_SCANNING_MODE_OFF)
+	}
+
+	if s.SecretScanningRules == nil {
+		s.SecretScanningRules = make(map[string]string)
+		for name, rule := range defaultSecretScanningRules {
+			s.SecretScanningRules[name] = rule
+		}
+	}
+
+	if s.SecretScanningAlertChannelId == nil {
+		s.SecretScanningAlertChannelId = NewString("")
+	}
+
+	if s.SecretScanningTimeBudgetMilliseconds == nil {
+		s.SecretScanningTimeBudgetMilliseconds = NewInt(SERVICE_SETTINGS_DEFAULT_SECRET_SCANNING_TIME_BUDGET_MILLISECONDS)
+	}
+
+	if s.WordFilterMode == nil {
+		s.WordFilterMode = NewString(WORD_FILTER_MODE_OFF)
+	}
+
+	if s.FilteredWords == nil {
+		s.FilteredWords = []string{}
+	}
+
+	if s.WordFilterExemptSystemAdmins == nil {
+		s.WordFilterExemptSystemAdmins = NewBool(false)
+	}
+
+	if s.LinkBlocklistMode == nil {
+		s.LinkBlocklistMode = NewString(LINK_BLOCKLIST_MODE_OFF)
+	}
+
+	if s.EnableChannelFeeds == nil {
+		s.EnableChannelFeeds = NewBool(false)
+	}
+
+	if s.ChannelFeedPostCount == nil {
+		s.ChannelFeedPostCount = NewInt(SERVICE_SETTINGS_DEFAULT_CHANNEL_FEED_POST_COUNT)
+	}
+
 	if s.EnablePreviewFeatures == nil {
 		s.EnablePreviewFeatures = NewBool(true)
 	}
@@ -434,6 +842,10 @@ func (s *ServiceSettings) SetDefaults() {
 		s.ExperimentalEnableDefaultChannelLeaveJoinMessages = NewBool(true)
 	}
 
+	if s.EnableJoinLeaveMessagesByDefault == nil {
+		s.EnableJoinLeaveMessagesByDefault = NewBool(true)
+	}
+
 	if s.ExperimentalGroupUnreadChannels == nil {
 		s.ExperimentalGroupUnreadChannels = NewString(GROUP_UNREAD_CHANNELS_DISABLED)
 	} else if *s.ExperimentalGroupUnreadChannels == "0" {
@@ -457,6 +869,115 @@ func (s *ServiceSettings) SetDefaults() {
 	if s.EnableAPITeamDeletion == nil {
 		s.EnableAPITeamDeletion = NewBool(false)
 	}
+
+	if s.EnableAPIChannelDeletion == nil {
+		s.EnableAPIChannelDeletion = NewBool(false)
+	}
+
+	// 0 permanently deletes channels the next time that the deletion job runs
+	if s.ChannelDeletionGracePeriodDays == nil {
+		s.ChannelDeletionGracePeriodDays = NewInt(SERVICE_SETTINGS_DEFAULT_CHANNEL_DELETION_GRACE_DAYS)
+	}
+
+	// 0 uses one worker per CPU
+	if s.PostPipelineWorkers == nil {
+		s.PostPipelineWorkers = NewInt(0)
+	}
+
+	if s.PostPipelineQueueSize == nil {
+		s.PostPipelineQueueSize = NewInt(SERVICE_SETTINGS_DEFAULT_POST_PIPELINE_QUEUE_SIZE)
+	}
+
+	// 0 creates a new post every time, even if the client sends the same pending post id again
+	if s.PostIdempotencyWindowSeconds == nil {
+		s.PostIdempotencyWindowSeconds = NewInt(SERVICE_SETTINGS_DEFAULT_POST_IDEMPOTENCY_WINDOW_SECONDS)
+	}
+
+	if s.PostIdempotencyCacheSize == nil {
+		s.PostIdempotencyCacheSize = NewInt(SERVICE_SETTINGS_DEFAULT_POST_IDEMPOTENCY_CACHE_SIZE)
+	}
+
+	// 0 writes channel views and statuses to the database immediately
+	if s.WriteBehindIntervalMilliseconds == nil {
+		s.WriteBehindIntervalMilliseconds = NewInt(SERVICE_SETTINGS_DEFAULT_WRITE_BEHIND_INTERVAL_MILLISECONDS)
+	}
+
+	// An empty header always uses the address of the client connected to the server
+	if s.TrustedProxyIPHeader == nil {
+		s.TrustedProxyIPHeader = NewString(SERVICE_SETTINGS_DEFAULT_TRUSTED_PROXY_IP_HEADER)
+	}
+
+	if s.TrustedProxyCIDRs == nil {
+		s.TrustedProxyCIDRs = NewString(SERVICE_SETTINGS_DEFAULT_TRUSTED_PROXY_CIDRS)
+	}
+
+	if s.DisabledAPIEndpoints == nil {
+		s.DisabledAPIEndpoints = []string{}
+	}
+
+	if s.EnableShortPermalinks == nil {
+		s.EnableShortPermalinks = NewBool(false)
+	}
+
+	if s.ShortPermalinkExpiryDays == nil {
+		s.ShortPermalinkExpiryDays = NewInt(SERVICE_SETTINGS_DEFAULT_SHORT_PERMALINK_EXPIRY_DAYS)
+	}
+
+	if s.ConfigHistoryMaxVersions == nil {
+		s.ConfigHistoryMaxVersions = NewInt(SERVICE_SETTINGS_DEFAULT_CONFIG_HISTORY_MAX_VERSIONS)
+	}
+
+	if s.EnableRequestTracing == nil {
+		s.EnableRequestTracing = NewBool(false)
+	}
+
+	if s.RequestTracingSampleRate == nil {
+		s.RequestTracingSampleRate = NewFloat64(SERVICE_SETTINGS_DEFAULT_REQUEST_TRACING_SAMPLE_RATE)
+	}
+
+	if s.RequestTracingSlowThresholdMilliseconds == nil {
+		s.RequestTracingSlowThresholdMilliseconds = NewInt(SERVICE_SETTINGS_DEFAULT_REQUEST_TRACING_SLOW_THRESHOLD_MILLISECONDS)
+	}
+}
+
+// defaultSecretScanningRules are the patterns that posts are scanned for unless others are configured, keyed by the
+// name that's reported when one of them matches.
+var defaultSecretScanningRules = map[string]string{
+	"aws_access_key_id":     `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+	"aws_secret_access_key": `(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?[A-Za-z0-9/+=]{40}\b`,
+	"slack_token":           `\bxox[abposr]-[0-9A-Za-z-]{10,}`,
+	"private_key":           `-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY-----`,
+}
+
+// GetPostEditTimeLimit returns the number of seconds after a post is created that a user with the given
+// roles can edit it for, or -1 if they can always edit it. When more than one of the roles has its own
+// limit, the most generous one applies.
+func (s *ServiceSettings) GetPostEditTimeLimit(roles []string) int {
+	return postTimeLimitForRoles(*s.PostEditTimeLimit, s.PostEditTimeLimitByRole, roles)
+}
+
+// GetPostDeleteTimeLimit is like GetPostEditTimeLimit, but for deleting posts.
+func (s *ServiceSettings) GetPostDeleteTimeLimit(roles []string) int {
+	return postTimeLimitForRoles(*s.PostDeleteTimeLimit, s.PostDeleteTimeLimitByRole, roles)
+}
+
+func postTimeLimitForRoles(defaultLimit int, limitsByRole map[string]int, roles []string) int {
+	limit := defaultLimit
+	found := false
+
+	for _, role := range roles {
+		roleLimit, ok := limitsByRole[role]
+		if !ok {
+			continue
+		}
+
+		if !found || roleLimit == -1 || (limit != -1 && roleLimit > limit) {
+			limit = roleLimit
+		}
+		found = true
+	}
+
+	return limit
 }
 
 type ClusterSettings struct {
@@ -541,12 +1062,25 @@ func (s *MetricsSettings) SetDefaults() {
 
 type AnalyticsSettings struct {
 	MaxUsersForStatistics *int
+	DailyRetentionDays    *int
+
+	// MinimumUserActivity leaves the users whose posts and reactions over a period add up to less than it
+	// out of the per user analytics, so that the figures of barely active users can't single them out.
+	MinimumUserActivity *int
 }
 
 func (s *AnalyticsSettings) SetDefaults() {
 	if s.MaxUsersForStatistics == nil {
 		s.MaxUsersForStatistics = NewInt(ANALYTICS_SETTINGS_DEFAULT_MAX_USERS_FOR_STATISTICS)
 	}
+
+	if s.DailyRetentionDays == nil {
+		s.DailyRetentionDays = NewInt(ANALYTICS_SETTINGS_DEFAULT_DAILY_RETENTION_DAYS)
+	}
+
+	if s.MinimumUserActivity == nil {
+		s.MinimumUserActivity = NewInt(ANALYTICS_SETTINGS_DEFAULT_MINIMUM_USER_ACTIVITY)
+	}
 }
 
 type SSOSettings struct {
@@ -560,15 +1094,18 @@ type SSOSettings struct {
 }
 
 type SqlSettings struct {
-	DriverName               *string
-	DataSource               *string
-	DataSourceReplicas       []string
-	DataSourceSearchReplicas []string
-	MaxIdleConns             *int
-	MaxOpenConns             *int
-	Trace                    bool
-	AtRestEncryptKey         string
-	QueryTimeout             *int
+	DriverName                  *string
+	DataSource                  *string
+	DataSourceReplicas          []string
+	DataSourceSearchReplicas    []string
+	DataSourceFailover          *string
+	MaxIdleConns                *int
+	MaxOpenConns                *int
+	Trace                       bool
+	AtRestEncryptKey            string
+	QueryTimeout                *int
+	HealthCheckIntervalSeconds  *int
+	HealthCheckFailureThreshold *int
 }
 
 func (s *SqlSettings) SetDefaults() {
@@ -595,6 +1132,18 @@ func (s *SqlSettings) SetDefaults() {
 	if s.QueryTimeout == nil {
 		s.QueryTimeout = NewInt(30)
 	}
+
+	if s.DataSourceFailover == nil {
+		s.DataSourceFailover = NewString("")
+	}
+
+	if s.HealthCheckIntervalSeconds == nil {
+		s.HealthCheckIntervalSeconds = NewInt(SQL_SETTINGS_DEFAULT_HEALTH_CHECK_INTERVAL_SECONDS)
+	}
+
+	if s.HealthCheckFailureThreshold == nil {
+		s.HealthCheckFailureThreshold = NewInt(SQL_SETTINGS_DEFAULT_HEALTH_CHECK_FAILURE_THRESHOLD)
+	}
 }
 
 type LogSettings struct {
@@ -654,24 +1203,31 @@ func (s *PasswordSettings) SetDefaults() {
 }
 
 type FileSettings struct {
-	EnableFileAttachments   *bool
-	EnableMobileUpload      *bool
-	EnableMobileDownload    *bool
-	MaxFileSize             *int64
-	DriverName              *string
-	Directory               string
-	EnablePublicLink        bool
-	PublicLinkSalt          *string
-	InitialFont             string
-	AmazonS3AccessKeyId     string
-	AmazonS3SecretAccessKey string
-	AmazonS3Bucket          string
-	AmazonS3Region          string
-	AmazonS3Endpoint        string
-	AmazonS3SSL             *bool
-	AmazonS3SignV2          *bool
-	AmazonS3SSE             *bool
-	AmazonS3Trace           *bool
+	EnableFileAttachments      *bool
+	EnableMobileUpload         *bool
+	EnableMobileDownload       *bool
+	MaxFileSize                *int64
+	MaxFileAttachments         *int
+	UserStorageQuota           *int64
+	TeamStorageQuota           *int64
+	DriverName                 *string
+	Directory                  string
+	EnablePublicLink           bool
+	EnableLegacyPublicLinks    *bool
+	PublicLinkSalt             *string
+	InlineContentTypes         []string
+	EnableProfileImageInitials *bool
+	DeduplicateAcrossUsers     *bool
+	InitialFont                string
+	AmazonS3AccessKeyId        string
+	AmazonS3SecretAccessKey    string
+	AmazonS3Bucket             string
+	AmazonS3Region             string
+	AmazonS3Endpoint           string
+	AmazonS3SSL                *bool
+	AmazonS3SignV2             *bool
+	AmazonS3SSE                *bool
+	AmazonS3Trace              *bool
 }
 
 func (s *FileSettings) SetDefaults() {
@@ -717,10 +1273,54 @@ func (s *FileSettings) SetDefaults() {
 		s.MaxFileSize = NewInt64(52428800) // 50 MB
 	}
 
+	if s.MaxFileAttachments == nil {
+		s.MaxFileAttachments = NewInt(10) // per post
+	}
+
+	if s.UserStorageQuota == nil {
+		s.UserStorageQuota = NewInt64(0) // Unlimited
+	}
+
+	if s.TeamStorageQuota == nil {
+		s.TeamStorageQuota = NewInt64(0) // Unlimited
+	}
+
+	if s.EnableLegacyPublicLinks == nil {
+		// Keeps the links that were made from the public link salt before links were saved working
+		s.EnableLegacyPublicLinks = NewBool(true)
+	}
+
 	if s.PublicLinkSalt == nil || len(*s.PublicLinkSalt) == 0 {
 		s.PublicLinkSalt = NewString(NewRandomString(32))
 	}
 
+	if s.InlineContentTypes == nil {
+		// Types that render in the browser without running anything
+		s.InlineContentTypes = []string{
+			"image/jpeg",
+			"image/png",
+			"image/bmp",
+			"image/gif",
+			"video/avi",
+			"video/mpeg",
+			"video/mp4",
+			"audio/mpeg",
+			"audio/wav",
+			"application/pdf",
+			"text/plain",
+		}
+	}
+
+	if s.EnableProfileImageInitials == nil {
+		// Users without a profile image get one with their initials instead of the first letter of their username
+		s.EnableProfileImageInitials = NewBool(true)
+	}
+
+	if s.DeduplicateAcrossUsers == nil {
+		// Uploads only share the stored file with earlier uploads of the same file by the same user
+		s.DeduplicateAcrossUsers = NewBool(false)
+	}
+
 	if s.InitialFont == "" {
 		// Defaults to "luximbi.ttf"
 		s.InitialFont = "luximbi.ttf"
@@ -760,6 +1360,8 @@ type EmailSettings struct {
 	LoginButtonColor                  *string
 	LoginButtonBorderColor            *string
 	LoginButtonTextColor              *string
+	QueuedEmailsPerMinute             *int
+	BounceWebhookSecret               *string
 }
 
 func (s *EmailSettings) SetDefaults() {
@@ -843,6 +1445,14 @@ func (s *EmailSettings) SetDefaults() {
 	if s.LoginButtonTextColor == nil {
 		s.LoginButtonTextColor = NewString("#2389D7")
 	}
+
+	if s.QueuedEmailsPerMinute == nil {
+		s.QueuedEmailsPerMinute = NewInt(EMAIL_SETTINGS_DEFAULT_QUEUED_EMAILS_PER_MINUTE)
+	}
+
+	if s.BounceWebhookSecret == nil {
+		s.BounceWebhookSecret = NewString("")
+	}
 }
 
 type RateLimitSettings struct {
@@ -893,6 +1503,8 @@ type SupportSettings struct {
 	HelpLink           *string
 	ReportAProblemLink *string
 	SupportEmail       *string
+
+	CustomTermsOfServiceEnabled *bool
 }
 
 func (s *SupportSettings) SetDefaults() {
@@ -939,6 +1551,10 @@ func (s *SupportSettings) SetDefaults() {
 	if s.SupportEmail == nil {
 		s.SupportEmail = NewString(SUPPORT_SETTINGS_DEFAULT_SUPPORT_EMAIL)
 	}
+
+	if s.CustomTermsOfServiceEnabled == nil {
+		s.CustomTermsOfServiceEnabled = NewBool(false)
+	}
 }
 
 type AnnouncementSettings struct {
@@ -947,6 +1563,8 @@ type AnnouncementSettings struct {
 	BannerColor          *string
 	BannerTextColor      *string
 	AllowBannerDismissal *bool
+	EnableWelcomeMessage *bool
+	WelcomeMessage       *string
 }
 
 func (s *AnnouncementSettings) SetDefaults() {
@@ -969,6 +1587,14 @@ func (s *AnnouncementSettings) SetDefaults() {
 	if s.AllowBannerDismissal == nil {
 		s.AllowBannerDismissal = NewBool(true)
 	}
+
+	if s.EnableWelcomeMessage == nil {
+		s.EnableWelcomeMessage = NewBool(false)
+	}
+
+	if s.WelcomeMessage == nil {
+		s.WelcomeMessage = NewString(ANNOUNCEMENT_SETTINGS_DEFAULT_WELCOME_MESSAGE)
+	}
 }
 
 type ThemeSettings struct {
@@ -997,34 +1623,40 @@ func (s *ThemeSettings) SetDefaults() {
 }
 
 type TeamSettings struct {
-	SiteName                            string
-	MaxUsersPerTeam                     *int
-	EnableTeamCreation                  *bool
-	EnableUserCreation                  *bool
-	EnableOpenServer                    *bool
-	RestrictCreationToDomains           string
-	EnableCustomBrand                   *bool
-	CustomBrandText                     *string
-	CustomDescriptionText               *string
-	RestrictDirectMessage               *string
-	RestrictTeamInvite                  *string
-	RestrictPublicChannelManagement     *string
-	RestrictPrivateChannelManagement    *string
-	RestrictPublicChannelCreation       *string
-	RestrictPrivateChannelCreation      *string
-	RestrictPublicChannelDeletion       *string
-	RestrictPrivateChannelDeletion      *string
-	RestrictPrivateChannelManageMembers *string
-	EnableXToLeaveChannelsFromLHS       *bool
-	UserStatusAwayTimeout               *int64
-	MaxChannelsPerTeam                  *int64
-	MaxNotificationsPerChannel          *int64
-	EnableConfirmNotificationsToChannel *bool
-	TeammateNameDisplay                 *string
-	ExperimentalEnableAutomaticReplies  *bool
-	ExperimentalHideTownSquareinLHS     *bool
-	ExperimentalTownSquareIsReadOnly    *bool
-	ExperimentalPrimaryTeam             *string
+	SiteName                               string
+	MaxUsersPerTeam                        *int
+	EnableTeamCreation                     *bool
+	EnableUserCreation                     *bool
+	EnableOpenServer                       *bool
+	RestrictCreationToDomains              string
+	EnableCustomBrand                      *bool
+	CustomBrandText                        *string
+	CustomDescriptionText                  *string
+	RestrictDirectMessage                  *string
+	RestrictTeamInvite                     *string
+	RestrictPublicChannelManagement        *string
+	RestrictPrivateChannelManagement       *string
+	RestrictPublicChannelCreation          *string
+	RestrictPrivateChannelCreation         *string
+	RestrictPublicChannelDeletion          *string
+	RestrictPrivateChannelDeletion         *string
+	RestrictPrivateChannelManageMembers    *string
+	RestrictGroupChannelManageMembers      *string
+	EnableGroupChannelRemovedMemberHistory *bool
+	DefaultChannelNotifyProps              map[string]string
+	EnableXToLeaveChannelsFromLHS          *bool
+	UserStatusAwayTimeout                  *int64
+	MaxChannelsPerTeam                     *int64
+	MaxNotificationsPerChannel             *int64
+	MaxChannelHeaderLength                 *int
+	MaxChannelPurposeLength                *int
+	EnableConfirmNotificationsToChannel    *bool
+	EnablePrivateToPublicConversion        *bool
+	TeammateNameDisplay                    *string
+	ExperimentalEnableAutomaticReplies     *bool
+	ExperimentalHideTownSquareinLHS        *bool
+	ExperimentalTownSquareIsReadOnly       *bool
+	ExperimentalPrimaryTeam                *string
 }
 
 func (s *TeamSettings) SetDefaults() {
@@ -1056,6 +1688,18 @@ func (s *TeamSettings) SetDefaults() {
 		s.RestrictTeamInvite = NewString(PERMISSIONS_ALL)
 	}
 
+	if s.RestrictGroupChannelManageMembers == nil {
+		s.RestrictGroupChannelManageMembers = NewString(GROUP_CHANNEL_MANAGE_MEMBERS_ANY)
+	}
+
+	if s.EnableGroupChannelRemovedMemberHistory == nil {
+		s.EnableGroupChannelRemovedMemberHistory = NewBool(false)
+	}
+
+	if s.DefaultChannelNotifyProps == nil {
+		s.DefaultChannelNotifyProps = GetDefaultChannelNotifyProps()
+	}
+
 	if s.RestrictPublicChannelManagement == nil {
 		s.RestrictPublicChannelManagement = NewString(PERMISSIONS_ALL)
 	}
@@ -1113,10 +1757,22 @@ func (s *TeamSettings) SetDefaults() {
 		s.MaxNotificationsPerChannel = NewInt64(1000)
 	}
 
+	if s.MaxChannelHeaderLength == nil {
+		s.MaxChannelHeaderLength = NewInt(TEAM_SETTINGS_DEFAULT_MAX_CHANNEL_HEADER)
+	}
+
+	if s.MaxChannelPurposeLength == nil {
+		s.MaxChannelPurposeLength = NewInt(TEAM_SETTINGS_DEFAULT_MAX_CHANNEL_PURPOSE)
+	}
+
 	if s.EnableConfirmNotificationsToChannel == nil {
 		s.EnableConfirmNotificationsToChannel = NewBool(true)
 	}
 
+	if s.EnablePrivateToPublicConversion == nil {
+		s.EnablePrivateToPublicConversion = NewBool(false)
+	}
+
 	if s.ExperimentalEnableAutomaticReplies == nil {
 		s.ExperimentalEnableAutomaticReplies = NewBool(false)
 	}
@@ -1636,32 +2292,240 @@ func (s *DataRetentionSettings) SetDefaults() {
 	}
 }
 
-type JobSettings struct {
-	RunJobs      *bool
-	RunScheduler *bool
+type BasicRetentionSettings struct {
+	Enable               *bool
+	MessageRetentionDays *int
+	FileRetentionDays    *int
+	ExemptPinnedPosts    *bool
+	DeletionJobStartTime *string
+	BatchSize            *int
 }
 
-func (s *JobSettings) SetDefaults() {
-	if s.RunJobs == nil {
-		s.RunJobs = NewBool(true)
+func (s *BasicRetentionSettings) SetDefaults() {
+	if s.Enable == nil {
+		s.Enable = NewBool(false)
 	}
 
-	if s.RunScheduler == nil {
-		s.RunScheduler = NewBool(true)
+	if s.MessageRetentionDays == nil {
+		s.MessageRetentionDays = NewInt(0)
 	}
-}
 
-type PluginState struct {
-	Enable bool
-}
+	if s.FileRetentionDays == nil {
+		s.FileRetentionDays = NewInt(0)
+	}
+
+	if s.ExemptPinnedPosts == nil {
+		s.ExemptPinnedPosts = NewBool(false)
+	}
+
+	if s.DeletionJobStartTime == nil {
+		s.DeletionJobStartTime = NewString(BASIC_RETENTION_SETTINGS_DEFAULT_DELETION_JOB_START_TIME)
+	}
+
+	if s.BatchSize == nil {
+		s.BatchSize = NewInt(BASIC_RETENTION_SETTINGS_DEFAULT_BATCH_SIZE)
+	}
+}
+
+type DeactivationSettings struct {
+	EnableIdleDeactivation *bool
+	IdleDays               *int
+	GracePeriodDays        *int
+	ExemptRoles            *string
+	ExemptBots             *bool
+	DryRun                 *bool
+	JobStartTime           *string
+	ArchiveDirectChannels  *bool
+}
+
+func (s *DeactivationSettings) SetDefaults() {
+	if s.EnableIdleDeactivation == nil {
+		s.EnableIdleDeactivation = NewBool(false)
+	}
+
+	if s.IdleDays == nil {
+		s.IdleDays = NewInt(DEACTIVATION_SETTINGS_DEFAULT_IDLE_DAYS)
+	}
+
+	if s.GracePeriodDays == nil {
+		s.GracePeriodDays = NewInt(DEACTIVATION_SETTINGS_DEFAULT_GRACE_PERIOD_DAYS)
+	}
+
+	if s.ExemptRoles == nil {
+		s.ExemptRoles = NewString(DEACTIVATION_SETTINGS_DEFAULT_EXEMPT_ROLES)
+	}
+
+	if s.ExemptBots == nil {
+		s.ExemptBots = NewBool(true)
+	}
+
+	if s.DryRun == nil {
+		s.DryRun = NewBool(false)
+	}
+
+	if s.JobStartTime == nil {
+		s.JobStartTime = NewString(DEACTIVATION_SETTINGS_DEFAULT_JOB_START_TIME)
+	}
+
+	if s.ArchiveDirectChannels == nil {
+		s.ArchiveDirectChannels = NewBool(false)
+	}
+}
+
+type ChannelArchivingSettings struct {
+	EnableInactiveChannelArchiving *bool
+	InactiveDays                   *int
+	WarningDays                    *int
+	ExemptTeams                    *string
+	ExemptChannelNamePattern       *string
+	DryRun                         *bool
+	JobStartTime                   *string
+}
+
+func (s *ChannelArchivingSettings) SetDefaults() {
+	if s.EnableInactiveChannelArchiving == nil {
+		s.EnableInactiveChannelArchiving = NewBool(false)
+	}
+
+	if s.InactiveDays == nil {
+		s.InactiveDays = NewInt(CHANNEL_ARCHIVING_SETTINGS_DEFAULT_INACTIVE_DAYS)
+	}
+
+	if s.WarningDays == nil {
+		s.WarningDays = NewInt(CHANNEL_ARCHIVING_SETTINGS_DEFAULT_WARNING_DAYS)
+	}
+
+	if s.ExemptTeams == nil {
+		s.ExemptTeams = NewString("")
+	}
+
+	if s.ExemptChannelNamePattern == nil {
+		s.ExemptChannelNamePattern = NewString("")
+	}
+
+	if s.DryRun == nil {
+		s.DryRun = NewBool(false)
+	}
+
+	if s.JobStartTime == nil {
+		s.JobStartTime = NewString(CHANNEL_ARCHIVING_SETTINGS_DEFAULT_JOB_START_TIME)
+	}
+}
+
+type UsernamePolicySettings struct {
+	Enable                 *bool
+	UsernamePattern        *string
+	ReservedUsernames      *string
+	BlockNameImpersonation *bool
+	ExemptLdapUsers        *bool
+}
+
+func (s *UsernamePolicySettings) SetDefaults() {
+	if s.Enable == nil {
+		s.Enable = NewBool(false)
+	}
+
+	if s.UsernamePattern == nil {
+		s.UsernamePattern = NewString("")
+	}
+
+	if s.ReservedUsernames == nil {
+		s.ReservedUsernames = NewString("")
+	}
+
+	if s.BlockNameImpersonation == nil {
+		s.BlockNameImpersonation = NewBool(false)
+	}
+
+	if s.ExemptLdapUsers == nil {
+		s.ExemptLdapUsers = NewBool(true)
+	}
+}
+
+type CaptchaSettings struct {
+	Enable               *bool
+	Provider             *string // CAPTCHA_PROVIDER_RECAPTCHA, or CAPTCHA_PROVIDER_PLUGIN to have the plugin with PluginId verify CAPTCHAs
+	SiteKey              *string
+	SecretKey            *string
+	VerifyURL            *string // Any service that verifies tokens the way that reCAPTCHA's siteverify does can be used
+	VerifyTimeoutSeconds *int
+	PluginId             *string
+	LoginFailuresPerIp   *int // Failed logins from an IP address before a CAPTCHA is needed to log in from it, or 0 to never need one
+	LoginFailuresPerUser *int // Failed logins for a user before a CAPTCHA is needed to log in as them, or 0 to never need one
+	FailureWindowMinutes *int // Failed logins are forgotten once there haven't been any more for this long
+}
+
+func (s *CaptchaSettings) SetDefaults() {
+	if s.Enable == nil {
+		s.Enable = NewBool(false)
+	}
+
+	if s.Provider == nil {
+		s.Provider = NewString(CAPTCHA_PROVIDER_RECAPTCHA)
+	}
+
+	if s.SiteKey == nil {
+		s.SiteKey = NewString("")
+	}
+
+	if s.SecretKey == nil {
+		s.SecretKey = NewString("")
+	}
+
+	if s.VerifyURL == nil {
+		s.VerifyURL = NewString(CAPTCHA_SETTINGS_DEFAULT_VERIFY_URL)
+	}
+
+	if s.VerifyTimeoutSeconds == nil {
+		s.VerifyTimeoutSeconds = NewInt(CAPTCHA_SETTINGS_DEFAULT_VERIFY_TIMEOUT_SECONDS)
+	}
+
+	if s.PluginId == nil {
+		s.PluginId = NewString("")
+	}
+
+	if s.LoginFailuresPerIp == nil {
+		s.LoginFailuresPerIp = NewInt(CAPTCHA_SETTINGS_DEFAULT_LOGIN_FAILURES_PER_IP)
+	}
+
+	if s.LoginFailuresPerUser == nil {
+		s.LoginFailuresPerUser = NewInt(CAPTCHA_SETTINGS_DEFAULT_LOGIN_FAILURES_PER_USER)
+	}
+
+	if s.FailureWindowMinutes == nil {
+		s.FailureWindowMinutes = NewInt(CAPTCHA_SETTINGS_DEFAULT_FAILURE_WINDOW_MINUTES)
+	}
+}
+
+type JobSettings struct {
+	RunJobs      *bool
+	RunScheduler *bool
+}
+
+func (s *JobSettings) SetDefaults() {
+	if s.RunJobs == nil {
+		s.RunJobs = NewBool(true)
+	}
+
+	if s.RunScheduler == nil {
+		s.RunScheduler = NewBool(true)
+	}
+}
+
+type PluginState struct {
+	Enable bool
+}
 
 type PluginSettings struct {
-	Enable          *bool
-	EnableUploads   *bool
-	Directory       *string
-	ClientDirectory *string
-	Plugins         map[string]interface{}
-	PluginStates    map[string]*PluginState
+	Enable                          *bool
+	EnableUploads                   *bool
+	Directory                       *string
+	ClientDirectory                 *string
+	InstallFromURLMaxFileSize       *int64
+	InstallFromURLTimeoutSeconds    *int
+	InstallFromURLCACertificateFile *string // PEM file of CA certificates trusted when downloading plugins, in addition to the system ones
+	Plugins                         map[string]interface{}
+	PluginStates                    map[string]*PluginState
 }
 
 func (s *PluginSettings) SetDefaults() {
@@ -1689,6 +2553,18 @@ func (s *PluginSettings) SetDefaults() {
 		*s.ClientDirectory = PLUGIN_SETTINGS_DEFAULT_CLIENT_DIRECTORY
 	}
 
+	if s.InstallFromURLMaxFileSize == nil {
+		s.InstallFromURLMaxFileSize = NewInt64(PLUGIN_SETTINGS_DEFAULT_INSTALL_FROM_URL_MAX_FILE_SIZE)
+	}
+
+	if s.InstallFromURLTimeoutSeconds == nil {
+		s.InstallFromURLTimeoutSeconds = NewInt(PLUGIN_SETTINGS_DEFAULT_INSTALL_FROM_URL_TIMEOUT_SECONDS)
+	}
+
+	if s.InstallFromURLCACertificateFile == nil {
+		s.InstallFromURLCACertificateFile = NewString("")
+	}
+
 	if s.Plugins == nil {
 		s.Plugins = make(map[string]interface{})
 	}
@@ -1698,6 +2574,18 @@ func (s *PluginSettings) SetDefaults() {
 	}
 }
 
+func (s *PluginSettings) isValid() *AppError {
+	if *s.InstallFromURLMaxFileSize <= 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.plugin.install_from_url_max_file_size.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *s.InstallFromURLTimeoutSeconds <= 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.plugin.install_from_url_timeout_seconds.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	return nil
+}
+
 type GlobalRelayMessageExportSettings struct {
 	CustomerType *string // must be either A9 or A10, dictates SMTP server url
 	SmtpUsername *string
@@ -1786,41 +2674,151 @@ func (s *TimezoneSettings) SetDefaults() {
 	}
 }
 
+type GuestAccountsSettings struct {
+	Enable *bool
+}
+
+func (s *GuestAccountsSettings) SetDefaults() {
+	if s.Enable == nil {
+		s.Enable = NewBool(false)
+	}
+}
+
+type ScimSettings struct {
+	Enable *bool
+	// UserAuthService is how users provisioned over SCIM sign in, with their SCIM userName used as their auth data.
+	UserAuthService *string
+	// GroupMapping is what SCIM groups are synced to, either custom groups or teams with the same name.
+	GroupMapping *string
+}
+
+func (s *ScimSettings) SetDefaults() {
+	if s.Enable == nil {
+		s.Enable = NewBool(false)
+	}
+
+	if s.UserAuthService == nil {
+		s.UserAuthService = NewString(USER_AUTH_SERVICE_SAML)
+	}
+
+	if s.GroupMapping == nil {
+		s.GroupMapping = NewString(SCIM_GROUP_MAPPING_CUSTOM_GROUPS)
+	}
+}
+
+const (
+	CACHE_PROFILE_BY_IDS        = "profile_by_ids"
+	CACHE_CHANNEL_BY_NAME       = "channel_by_name"
+	CACHE_CHANNEL_MEMBER_COUNTS = "channel_member_counts"
+	CACHE_CHANNEL_MODERATIONS   = "channel_moderations"
+	CACHE_TEAM_FILTERED_WORDS   = "team_filtered_words"
+)
+
+// CacheSizeSettings configures one of the server's in-memory caches. Entries that are older than ExpirySeconds are
+// ignored, and they never expire if it's 0.
+type CacheSizeSettings struct {
+	Size          *int
+	ExpirySeconds *int
+}
+
+func (s *CacheSizeSettings) SetDefaults(size, expirySeconds int) {
+	if s.Size == nil {
+		s.Size = NewInt(size)
+	}
+
+	if s.ExpirySeconds == nil {
+		s.ExpirySeconds = NewInt(expirySeconds)
+	}
+}
+
+var defaultCacheSizes = map[string]struct{ size, expirySeconds int }{
+	CACHE_PROFILE_BY_IDS:        {SESSION_CACHE_SIZE, 15 * 60},
+	CACHE_CHANNEL_BY_NAME:       {CHANNEL_CACHE_SIZE, 15 * 60},
+	CACHE_CHANNEL_MEMBER_COUNTS: {CHANNEL_CACHE_SIZE, 30 * 60},
+	CACHE_CHANNEL_MODERATIONS:   {CHANNEL_CACHE_SIZE, 30 * 60},
+	CACHE_TEAM_FILTERED_WORDS:   {FILTERED_WORDS_CACHE_SIZE, 30 * 60},
+}
+
+type CacheSettings struct {
+	// Caches is keyed by cache name, such as CACHE_CHANNEL_BY_NAME. Names are lowercase since the keys of maps in
+	// the config file are case insensitive.
+	Caches map[string]*CacheSizeSettings
+}
+
+func (s *CacheSettings) SetDefaults() {
+	if s.Caches == nil {
+		s.Caches = make(map[string]*CacheSizeSettings)
+	}
+
+	for name, defaults := range defaultCacheSizes {
+		if s.Caches[name] == nil {
+			s.Caches[name] = &CacheSizeSettings{}
+		}
+		s.Caches[name].SetDefaults(defaults.size, defaults.expirySeconds)
+	}
+}
+
+// GetCacheSizeSettings returns the settings for the named cache, falling back to its defaults if it isn't configured.
+func (s *CacheSettings) GetCacheSizeSettings(name string) CacheSizeSettings {
+	settings := CacheSizeSettings{}
+	if configured := s.Caches[name]; configured != nil {
+		settings = *configured
+	}
+
+	defaults := defaultCacheSizes[name]
+	settings.SetDefaults(defaults.size, defaults.expirySeconds)
+
+	return settings
+}
+
 type ConfigFunc func() *Config
 
 type Config struct {
-	ServiceSettings       ServiceSettings
-	TeamSettings          TeamSettings
-	ClientRequirements    ClientRequirements
-	SqlSettings           SqlSettings
-	LogSettings           LogSettings
-	PasswordSettings      PasswordSettings
-	FileSettings          FileSettings
-	EmailSettings         EmailSettings
-	RateLimitSettings     RateLimitSettings
-	PrivacySettings       PrivacySettings
-	SupportSettings       SupportSettings
-	AnnouncementSettings  AnnouncementSettings
-	ThemeSettings         ThemeSettings
-	GitLabSettings        SSOSettings
-	GoogleSettings        SSOSettings
-	Office365Settings     SSOSettings
-	LdapSettings          LdapSettings
-	ComplianceSettings    ComplianceSettings
-	LocalizationSettings  LocalizationSettings
-	SamlSettings          SamlSettings
-	NativeAppSettings     NativeAppSettings
-	ClusterSettings       ClusterSettings
-	MetricsSettings       MetricsSettings
-	AnalyticsSettings     AnalyticsSettings
-	WebrtcSettings        WebrtcSettings
-	ElasticsearchSettings ElasticsearchSettings
-	DataRetentionSettings DataRetentionSettings
-	MessageExportSettings MessageExportSettings
-	JobSettings           JobSettings
-	PluginSettings        PluginSettings
-	DisplaySettings       DisplaySettings
-	TimezoneSettings      TimezoneSettings
+	ServiceSettings          ServiceSettings
+	TeamSettings             TeamSettings
+	ClientRequirements       ClientRequirements
+	SqlSettings              SqlSettings
+	LogSettings              LogSettings
+	PasswordSettings         PasswordSettings
+	FileSettings             FileSettings
+	EmailSettings            EmailSettings
+	RateLimitSettings        RateLimitSettings
+	PrivacySettings          PrivacySettings
+	SupportSettings          SupportSettings
+	AnnouncementSettings     AnnouncementSettings
+	ThemeSettings            ThemeSettings
+	GitLabSettings           SSOSettings
+	GoogleSettings           SSOSettings
+	Office365Settings        SSOSettings
+	LdapSettings             LdapSettings
+	ComplianceSettings       ComplianceSettings
+	LocalizationSettings     LocalizationSettings
+	SamlSettings             SamlSettings
+	NativeAppSettings        NativeAppSettings
+	ClusterSettings          ClusterSettings
+	MetricsSettings          MetricsSettings
+	AnalyticsSettings        AnalyticsSettings
+	WebrtcSettings           WebrtcSettings
+	ElasticsearchSettings    ElasticsearchSettings
+	DataRetentionSettings    DataRetentionSettings
+	BasicRetentionSettings   BasicRetentionSettings
+	DeactivationSettings     DeactivationSettings
+	ChannelArchivingSettings ChannelArchivingSettings
+	UsernamePolicySettings   UsernamePolicySettings
+	CaptchaSettings          CaptchaSettings
+	MessageExportSettings    MessageExportSettings
+	JobSettings              JobSettings
+	PluginSettings           PluginSettings
+	DisplaySettings          DisplaySettings
+	TimezoneSettings         TimezoneSettings
+	GuestAccountsSettings    GuestAccountsSettings
+	ScimSettings             ScimSettings
+	CacheSettings            CacheSettings
+	FeatureFlags             FeatureFlags
+
+	// ConfigVersion goes up by one every time the config is saved, so that a save can be checked against the
+	// version that it was based on rather than overwrite the changes that were saved in the meantime.
+	ConfigVersion *int64
 }
 
 func (o *Config) Clone() *Config {
@@ -1855,6 +2853,24 @@ func ConfigFromJson(data io.Reader) *Config {
 	return o
 }
 
+// ConfigConflict is the response to a save of a config that was based on an older version than the current one. It's
+// the error along with the current config, so clients that only look for the error still find it.
+type ConfigConflict struct {
+	AppError
+	Config *Config `json:"config"`
+}
+
+func (o *ConfigConflict) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func ConfigConflictFromJson(data io.Reader) *ConfigConflict {
+	var o *ConfigConflict
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
+
 func (o *Config) SetDefaults() {
 	o.LdapSettings.SetDefaults()
 	o.SamlSettings.SetDefaults()
@@ -1885,6 +2901,11 @@ func (o *Config) SetDefaults() {
 	o.ElasticsearchSettings.SetDefaults()
 	o.NativeAppSettings.SetDefaults()
 	o.DataRetentionSettings.SetDefaults()
+	o.BasicRetentionSettings.SetDefaults()
+	o.DeactivationSettings.SetDefaults()
+	o.ChannelArchivingSettings.SetDefaults()
+	o.UsernamePolicySettings.SetDefaults()
+	o.CaptchaSettings.SetDefaults()
 	o.RateLimitSettings.SetDefaults()
 	o.LogSettings.SetDefaults()
 	o.JobSettings.SetDefaults()
@@ -1892,6 +2913,14 @@ func (o *Config) SetDefaults() {
 	o.MessageExportSettings.SetDefaults()
 	o.TimezoneSettings.SetDefaults()
 	o.DisplaySettings.SetDefaults()
+	o.GuestAccountsSettings.SetDefaults()
+	o.ScimSettings.SetDefaults()
+	o.CacheSettings.SetDefaults()
+	o.FeatureFlags.SetDefaults()
+
+	if o.ConfigVersion == nil {
+		o.ConfigVersion = NewInt64(0)
+	}
 }
 
 func (o *Config) IsValid() *AppError {
@@ -1947,6 +2976,34 @@ func (o *Config) IsValid() *AppError {
 		return err
 	}
 
+	if err := o.BasicRetentionSettings.isValid(); err != nil {
+		return err
+	}
+
+	if err := o.DeactivationSettings.isValid(); err != nil {
+		return err
+	}
+
+	if err := o.ChannelArchivingSettings.isValid(); err != nil {
+		return err
+	}
+
+	if err := o.UsernamePolicySettings.isValid(); err != nil {
+		return err
+	}
+
+	if err := o.CaptchaSettings.isValid(); err != nil {
+		return err
+	}
+
+	if err := o.AnalyticsSettings.isValid(); err != nil {
+		return err
+	}
+
+	if err := o.AnnouncementSettings.isValid(); err != nil {
+		return err
+	}
+
 	if err := o.LocalizationSettings.isValid(); err != nil {
 		return err
 	}
@@ -1955,6 +3012,18 @@ func (o *Config) IsValid() *AppError {
 		return err
 	}
 
+	if err := o.ScimSettings.isValid(); err != nil {
+		return err
+	}
+
+	if err := o.CacheSettings.isValid(); err != nil {
+		return err
+	}
+
+	if err := o.PluginSettings.isValid(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -1971,10 +3040,26 @@ func (ts *TeamSettings) isValid() *AppError {
 		return NewAppError("Config.IsValid", "model.config.is_valid.max_notify_per_channel.app_error", nil, "", http.StatusBadRequest)
 	}
 
+	if *ts.MaxChannelHeaderLength <= 0 || *ts.MaxChannelHeaderLength > CHANNEL_HEADER_MAX_RUNES {
+		return NewAppError("Config.IsValid", "model.config.is_valid.max_channel_header.app_error", map[string]interface{}{"MaxLength": CHANNEL_HEADER_MAX_RUNES}, "", http.StatusBadRequest)
+	}
+
+	if *ts.MaxChannelPurposeLength <= 0 || *ts.MaxChannelPurposeLength > CHANNEL_PURPOSE_MAX_RUNES {
+		return NewAppError("Config.IsValid", "model.config.is_valid.max_channel_purpose.app_error", map[string]interface{}{"MaxLength": CHANNEL_PURPOSE_MAX_RUNES}, "", http.StatusBadRequest)
+	}
+
 	if !(*ts.RestrictDirectMessage == DIRECT_MESSAGE_ANY || *ts.RestrictDirectMessage == DIRECT_MESSAGE_TEAM) {
 		return NewAppError("Config.IsValid", "model.config.is_valid.restrict_direct_message.app_error", nil, "", http.StatusBadRequest)
 	}
 
+	if !(*ts.RestrictGroupChannelManageMembers == GROUP_CHANNEL_MANAGE_MEMBERS_NONE || *ts.RestrictGroupChannelManageMembers == GROUP_CHANNEL_MANAGE_MEMBERS_CREATOR || *ts.RestrictGroupChannelManageMembers == GROUP_CHANNEL_MANAGE_MEMBERS_ANY) {
+		return NewAppError("Config.IsValid", "model.config.is_valid.restrict_group_channel_manage_members.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if err := IsChannelNotifyPropsValid(ts.DefaultChannelNotifyProps); err != nil {
+		return NewAppError("Config.IsValid", "model.config.is_valid.default_channel_notify_props.app_error", nil, err.DetailedError, http.StatusBadRequest)
+	}
+
 	if !(*ts.TeammateNameDisplay == SHOW_FULLNAME || *ts.TeammateNameDisplay == SHOW_NICKNAME_FULLNAME || *ts.TeammateNameDisplay == SHOW_USERNAME) {
 		return NewAppError("Config.IsValid", "model.config.is_valid.teammate_name_display.app_error", nil, "", http.StatusBadRequest)
 	}
@@ -2011,6 +3096,14 @@ func (ss *SqlSettings) isValid() *AppError {
 		return NewAppError("Config.IsValid", "model.config.is_valid.sql_max_conn.app_error", nil, "", http.StatusBadRequest)
 	}
 
+	if *ss.HealthCheckIntervalSeconds < 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.sql_health_check_interval.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.HealthCheckFailureThreshold <= 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.sql_health_check_failure_threshold.app_error", nil, "", http.StatusBadRequest)
+	}
+
 	return nil
 }
 
@@ -2019,6 +3112,14 @@ func (fs *FileSettings) isValid() *AppError {
 		return NewAppError("Config.IsValid", "model.config.is_valid.max_file_size.app_error", nil, "", http.StatusBadRequest)
 	}
 
+	if *fs.MaxFileAttachments <= 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.max_file_attachments.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *fs.UserStorageQuota < 0 || *fs.TeamStorageQuota < 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.storage_quota.app_error", nil, "", http.StatusBadRequest)
+	}
+
 	if !(*fs.DriverName == IMAGE_DRIVER_LOCAL || *fs.DriverName == IMAGE_DRIVER_S3) {
 		return NewAppError("Config.IsValid", "model.config.is_valid.file_driver.app_error", nil, "", http.StatusBadRequest)
 	}
@@ -2027,6 +3128,12 @@ func (fs *FileSettings) isValid() *AppError {
 		return NewAppError("Config.IsValid", "model.config.is_valid.file_salt.app_error", nil, "", http.StatusBadRequest)
 	}
 
+	for _, contentType := range fs.InlineContentTypes {
+		if _, _, err := mime.ParseMediaType(contentType); err != nil {
+			return NewAppError("Config.IsValid", "model.config.is_valid.inline_content_types.app_error", map[string]interface{}{"ContentType": contentType}, "", http.StatusBadRequest)
+		}
+	}
+
 	return nil
 }
 
@@ -2051,6 +3158,10 @@ func (es *EmailSettings) isValid() *AppError {
 		return NewAppError("Config.IsValid", "model.config.is_valid.email_notification_contents_type.app_error", nil, "", http.StatusBadRequest)
 	}
 
+	if *es.QueuedEmailsPerMinute < 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.queued_emails_per_minute.app_error", nil, "", http.StatusBadRequest)
+	}
+
 	return nil
 }
 
@@ -2188,6 +3299,10 @@ func (ss *ServiceSettings) isValid() *AppError {
 		return NewAppError("Config.IsValid", "model.config.is_valid.webserver_security.app_error", nil, "", http.StatusBadRequest)
 	}
 
+	if *ss.ConfigWatchDebounceMilliseconds < 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.config_watch_debounce.app_error", nil, "", http.StatusBadRequest)
+	}
+
 	if *ss.ReadTimeout <= 0 {
 		return NewAppError("Config.IsValid", "model.config.is_valid.read_timeout.app_error", nil, "", http.StatusBadRequest)
 	}
@@ -2196,6 +3311,21 @@ func (ss *ServiceSettings) isValid() *AppError {
 		return NewAppError("Config.IsValid", "model.config.is_valid.write_timeout.app_error", nil, "", http.StatusBadRequest)
 	}
 
+	for _, allowed := range strings.Fields(*ss.AllowCorsFrom) {
+		// wildcards are only allowed for the whole list or at the start of a host
+		if allowed != "*" && strings.Contains(strings.Replace(allowed, "://*.", "://", 1), "*") {
+			return NewAppError("Config.IsValid", "model.config.is_valid.allow_cors_from.app_error", map[string]interface{}{"Origin": allowed}, "", http.StatusBadRequest)
+		}
+	}
+
+	if *ss.CorsMaxAge < 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.cors_max_age.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if _, err := time.LoadLocation(*ss.DefaultTimezone); err != nil {
+		return NewAppError("Config.IsValid", "model.config.is_valid.default_timezone.app_error", map[string]interface{}{"Timezone": *ss.DefaultTimezone}, err.Error(), http.StatusBadRequest)
+	}
+
 	if *ss.TimeBetweenUserTypingUpdatesMilliseconds < 1000 {
 		return NewAppError("Config.IsValid", "model.config.is_valid.time_between_user_typing.app_error", nil, "", http.StatusBadRequest)
 	}
@@ -2204,12 +3334,233 @@ func (ss *ServiceSettings) isValid() *AppError {
 		return NewAppError("Config.IsValid", "model.config.is_valid.login_attempts.app_error", nil, "", http.StatusBadRequest)
 	}
 
+	if *ss.ImpersonationSessionLengthInMinutes <= 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.impersonation_session_length.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.EnableLocalMode && *ss.LocalModeSocketLocation == "" {
+		return NewAppError("Config.IsValid", "model.config.is_valid.local_mode_socket_location.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.SessionCleanupIntervalMinutes <= 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.session_cleanup_interval.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.SessionCleanupBatchSize <= 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.session_cleanup_batch_size.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.SessionCleanupRetentionDays < 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.session_cleanup_retention_days.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.AuditRetentionDays < 0 || *ss.AuditRetentionMaxRows < 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.audit_retention.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.JobRetentionDays < 0 || *ss.JobRetentionMaxRows < 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.job_retention.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.TableTrimIntervalMinutes <= 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.table_trim_interval.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.TableTrimBatchSize <= 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.table_trim_batch_size.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.SessionAnomalyDetectionMode != SESSION_ANOMALY_DETECTION_MODE_LAX && *ss.SessionAnomalyDetectionMode != SESSION_ANOMALY_DETECTION_MODE_STRICT {
+		return NewAppError("Config.IsValid", "model.config.is_valid.session_anomaly_detection_mode.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	for _, cidr := range strings.Fields(*ss.SessionAnomalyAllowedCIDRs) {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return NewAppError("Config.IsValid", "model.config.is_valid.session_anomaly_allowed_cidrs.app_error", map[string]interface{}{"CIDR": cidr}, err.Error(), http.StatusBadRequest)
+		}
+	}
+
+	if *ss.PostEditTimeLimit < -1 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.post_edit_time_limit.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	for role, limit := range ss.PostEditTimeLimitByRole {
+		if limit < -1 {
+			return NewAppError("Config.IsValid", "model.config.is_valid.post_edit_time_limit.app_error", nil, "role="+role, http.StatusBadRequest)
+		}
+	}
+
+	if *ss.PostDeleteTimeLimit < -1 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.post_delete_time_limit.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	for role, limit := range ss.PostDeleteTimeLimitByRole {
+		if limit < -1 {
+			return NewAppError("Config.IsValid", "model.config.is_valid.post_delete_time_limit.app_error", nil, "role="+role, http.StatusBadRequest)
+		}
+	}
+
+	if *ss.UniqueEmojiReactionLimitPerPost < 0 || *ss.UniqueEmojiReactionLimitPerPost > SERVICE_SETTINGS_MAX_UNIQUE_EMOJI_REACTION_LIMIT_PER_POST {
+		return NewAppError("Config.IsValid", "model.config.is_valid.unique_emoji_reaction_limit_per_post.app_error", map[string]interface{}{"Max": SERVICE_SETTINGS_MAX_UNIQUE_EMOJI_REACTION_LIMIT_PER_POST}, "", http.StatusBadRequest)
+	}
+
+	if *ss.MaxPostPropsSize <= 0 || *ss.MaxPostPropsSize > POST_PROPS_MAX_RUNES {
+		return NewAppError("Config.IsValid", "model.config.is_valid.max_post_props_size.app_error", map[string]interface{}{"Max": POST_PROPS_MAX_RUNES}, "", http.StatusBadRequest)
+	}
+
+	switch *ss.SecretScanningMode {
+	case SECRET_SCANNING_MODE_OFF, SECRET_SCANNING_MODE_WARN, SECRET_SCANNING_MODE_BLOCK:
+	case SECRET_SCANNING_MODE_ALERT:
+		if !IsValidId(*ss.SecretScanningAlertChannelId) {
+			return NewAppError("Config.IsValid", "model.config.is_valid.secret_scanning_alert_channel_id.app_error", nil, "", http.StatusBadRequest)
+		}
+	default:
+		return NewAppError("Config.IsValid", "model.config.is_valid.secret_scanning_mode.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	for name, rule := range ss.SecretScanningRules {
+		if _, err := regexp.Compile(rule); err != nil {
+			return NewAppError("Config.IsValid", "model.config.is_valid.secret_scanning_rules.app_error", map[string]interface{}{"Name": name}, err.Error(), http.StatusBadRequest)
+		}
+	}
+
+	if *ss.SecretScanningTimeBudgetMilliseconds <= 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.secret_scanning_time_budget.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	switch *ss.WordFilterMode {
+	case WORD_FILTER_MODE_OFF, WORD_FILTER_MODE_MASK, WORD_FILTER_MODE_BLOCK, WORD_FILTER_MODE_FLAG:
+	default:
+		return NewAppError("Config.IsValid", "model.config.is_valid.word_filter_mode.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if len(ss.FilteredWords) > FILTERED_WORDS_MAX_COUNT {
+		return NewAppError("Config.IsValid", "model.config.is_valid.filtered_words_count.app_error", map[string]interface{}{"Max": FILTERED_WORDS_MAX_COUNT}, "", http.StatusBadRequest)
+	}
+
+	for _, word := range ss.FilteredWords {
+		if !IsValidFilteredWord(NormalizeFilteredWord(word)) {
+			return NewAppError("Config.IsValid", "model.config.is_valid.filtered_words.app_error", map[string]interface{}{"Word": word}, "", http.StatusBadRequest)
+		}
+	}
+
+	switch *ss.LinkBlocklistMode {
+	case LINK_BLOCKLIST_MODE_OFF, LINK_BLOCKLIST_MODE_WARN, LINK_BLOCKLIST_MODE_STRIP, LINK_BLOCKLIST_MODE_BLOCK:
+	default:
+		return NewAppError("Config.IsValid", "model.config.is_valid.link_blocklist_mode.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.ChannelFeedPostCount <= 0 || *ss.ChannelFeedPostCount > CHANNEL_FEED_MAX_POST_COUNT {
+		return NewAppError("Config.IsValid", "model.config.is_valid.channel_feed_post_count.app_error", map[string]interface{}{"Max": CHANNEL_FEED_MAX_POST_COUNT}, "", http.StatusBadRequest)
+	}
+
+	if len(*ss.PostActionSigningSecret) < 32 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.post_action_signing_secret.app_error", nil, "", http.StatusBadRequest)
+	}
+
 	if len(*ss.WebsocketURL) != 0 {
 		if _, err := url.ParseRequestURI(*ss.WebsocketURL); err != nil {
 			return NewAppError("Config.IsValid", "model.config.is_valid.websocket_url.app_error", nil, "", http.StatusBadRequest)
 		}
 	}
 
+	if *ss.WebsocketHubs < 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.websocket_hubs.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.WebsocketHubRebalanceIntervalSeconds < 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.websocket_hub_rebalance_interval.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.WebsocketSlowClientPolicy != WEBSOCKET_SLOW_CLIENT_POLICY_DROP && *ss.WebsocketSlowClientPolicy != WEBSOCKET_SLOW_CLIENT_POLICY_DISCONNECT {
+		return NewAppError("Config.IsValid", "model.config.is_valid.websocket_slow_client_policy.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.WebsocketSlowClientMaxDrops <= 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.websocket_slow_client_max_drops.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.WebsocketPingIntervalSeconds <= 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.websocket_ping_interval.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.WebsocketPongTimeoutSeconds <= 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.websocket_pong_timeout.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.WebsocketMaxMissedPongs <= 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.websocket_max_missed_pongs.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.WebsocketDrainWindowSeconds < 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.websocket_drain_window.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.MaxPresenceSubscriptionsPerConnection <= 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.max_presence_subscriptions.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.PostPipelineWorkers < 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.post_pipeline_workers.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.PostPipelineQueueSize <= 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.post_pipeline_queue_size.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.PostIdempotencyWindowSeconds < 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.post_idempotency_window.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.PostIdempotencyCacheSize <= 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.post_idempotency_cache_size.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.ChannelDeletionGracePeriodDays < 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.channel_deletion_grace_period.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.WriteBehindIntervalMilliseconds < 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.write_behind_interval.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if strings.ContainsAny(*ss.TrustedProxyIPHeader, " \t:") {
+		return NewAppError("Config.IsValid", "model.config.is_valid.trusted_proxy_ip_header.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	for _, cidr := range strings.Fields(*ss.TrustedProxyCIDRs) {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return NewAppError("Config.IsValid", "model.config.is_valid.trusted_proxy_cidrs.app_error", map[string]interface{}{"CIDR": cidr}, err.Error(), http.StatusBadRequest)
+		}
+	}
+
+	for _, endpoint := range ss.DisabledAPIEndpoints {
+		method, path, ok := ParseDisabledAPIEndpoint(endpoint)
+		if !ok {
+			return NewAppError("Config.IsValid", "model.config.is_valid.disabled_api_endpoint.app_error", map[string]interface{}{"Endpoint": endpoint}, "", http.StatusBadRequest)
+		}
+
+		if requiredMethod, required := requiredAPIEndpoints[path]; required && (method == "*" || method == requiredMethod) {
+			return NewAppError("Config.IsValid", "model.config.is_valid.disabled_api_endpoint_required.app_error", map[string]interface{}{"Endpoint": endpoint}, "", http.StatusBadRequest)
+		}
+	}
+
+	if *ss.ShortPermalinkExpiryDays <= 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.short_permalink_expiry_days.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.ConfigHistoryMaxVersions <= 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.config_history_max_versions.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.RequestTracingSampleRate < 0 || *ss.RequestTracingSampleRate > 1 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.request_tracing_sample_rate.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *ss.RequestTracingSlowThresholdMilliseconds < 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.request_tracing_slow_threshold.app_error", nil, "", http.StatusBadRequest)
+	}
+
 	if len(*ss.ListenAddress) == 0 {
 		return NewAppError("Config.IsValid", "model.config.is_valid.listen_address.app_error", nil, "", http.StatusBadRequest)
 	}
@@ -2221,8 +3572,8 @@ func (ss *ServiceSettings) isValid() *AppError {
 	}
 
 	switch *ss.ImageProxyType {
-	case "":
-	case "atmos/camo":
+	case "", IMAGE_PROXY_TYPE_LOCAL:
+	case IMAGE_PROXY_TYPE_ATMOS_CAMO:
 		if *ss.ImageProxyOptions == "" {
 			return NewAppError("Config.IsValid", "model.config.is_valid.atmos_camo_image_proxy_options.app_error", nil, "", http.StatusBadRequest)
 		}
@@ -2283,6 +3634,165 @@ func (drs *DataRetentionSettings) isValid() *AppError {
 	return nil
 }
 
+func (s *AnalyticsSettings) isValid() *AppError {
+	if *s.DailyRetentionDays <= 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.analytics.daily_retention_days.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *s.MinimumUserActivity < 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.analytics.minimum_user_activity.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (s *AnnouncementSettings) isValid() *AppError {
+	if _, err := RenderWelcomeMessage(*s.WelcomeMessage, WelcomeMessageValues{}); err != nil {
+		return NewAppError("Config.IsValid", "model.config.is_valid.announcement.welcome_message.app_error", nil, err.Error(), http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (s *ScimSettings) isValid() *AppError {
+	switch *s.UserAuthService {
+	case USER_AUTH_SERVICE_SAML, SERVICE_GITLAB, SERVICE_GOOGLE, SERVICE_OFFICE365:
+	default:
+		return NewAppError("Config.IsValid", "model.config.is_valid.scim.user_auth_service.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if !(*s.GroupMapping == SCIM_GROUP_MAPPING_CUSTOM_GROUPS || *s.GroupMapping == SCIM_GROUP_MAPPING_TEAMS) {
+		return NewAppError("Config.IsValid", "model.config.is_valid.scim.group_mapping.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (s *CacheSettings) isValid() *AppError {
+	for name, settings := range s.Caches {
+		if _, ok := defaultCacheSizes[name]; !ok {
+			return NewAppError("Config.IsValid", "model.config.is_valid.cache.name.app_error", map[string]interface{}{"Name": name}, "", http.StatusBadRequest)
+		}
+
+		if *settings.Size <= 0 {
+			return NewAppError("Config.IsValid", "model.config.is_valid.cache.size.app_error", map[string]interface{}{"Name": name}, "", http.StatusBadRequest)
+		}
+
+		if *settings.ExpirySeconds < 0 {
+			return NewAppError("Config.IsValid", "model.config.is_valid.cache.expiry_seconds.app_error", map[string]interface{}{"Name": name}, "", http.StatusBadRequest)
+		}
+	}
+
+	return nil
+}
+
+func (brs *BasicRetentionSettings) isValid() *AppError {
+	if *brs.MessageRetentionDays < 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.basic_retention.message_retention_days.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *brs.FileRetentionDays < 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.basic_retention.file_retention_days.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if _, err := time.Parse("15:04", *brs.DeletionJobStartTime); err != nil {
+		return NewAppError("Config.IsValid", "model.config.is_valid.basic_retention.deletion_job_start_time.app_error", nil, err.Error(), http.StatusBadRequest)
+	}
+
+	if *brs.BatchSize <= 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.basic_retention.batch_size.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (s *DeactivationSettings) isValid() *AppError {
+	if *s.IdleDays <= 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.deactivation.idle_days.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *s.GracePeriodDays < 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.deactivation.grace_period_days.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	for _, role := range strings.Fields(*s.ExemptRoles) {
+		if !IsValidRoleName(role) {
+			return NewAppError("Config.IsValid", "model.config.is_valid.deactivation.exempt_roles.app_error", map[string]interface{}{"Role": role}, "", http.StatusBadRequest)
+		}
+	}
+
+	if _, err := time.Parse("15:04", *s.JobStartTime); err != nil {
+		return NewAppError("Config.IsValid", "model.config.is_valid.deactivation.job_start_time.app_error", nil, err.Error(), http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (s *ChannelArchivingSettings) isValid() *AppError {
+	if *s.InactiveDays <= 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.channel_archiving.inactive_days.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *s.WarningDays < 0 || *s.WarningDays >= *s.InactiveDays {
+		return NewAppError("Config.IsValid", "model.config.is_valid.channel_archiving.warning_days.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if _, err := regexp.Compile(*s.ExemptChannelNamePattern); err != nil {
+		return NewAppError("Config.IsValid", "model.config.is_valid.channel_archiving.exempt_channel_name_pattern.app_error", nil, err.Error(), http.StatusBadRequest)
+	}
+
+	if _, err := time.Parse("15:04", *s.JobStartTime); err != nil {
+		return NewAppError("Config.IsValid", "model.config.is_valid.channel_archiving.job_start_time.app_error", nil, err.Error(), http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (s *UsernamePolicySettings) isValid() *AppError {
+	if _, err := regexp.Compile(*s.UsernamePattern); err != nil {
+		return NewAppError("Config.IsValid", "model.config.is_valid.username_policy.pattern.app_error", nil, err.Error(), http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (s *CaptchaSettings) isValid() *AppError {
+	if !*s.Enable {
+		return nil
+	}
+
+	switch *s.Provider {
+	case CAPTCHA_PROVIDER_RECAPTCHA:
+		if *s.SiteKey == "" || *s.SecretKey == "" {
+			return NewAppError("Config.IsValid", "model.config.is_valid.captcha.keys.app_error", nil, "", http.StatusBadRequest)
+		}
+
+		if !IsValidHttpUrl(*s.VerifyURL) {
+			return NewAppError("Config.IsValid", "model.config.is_valid.captcha.verify_url.app_error", nil, "", http.StatusBadRequest)
+		}
+	case CAPTCHA_PROVIDER_PLUGIN:
+		if *s.PluginId == "" {
+			return NewAppError("Config.IsValid", "model.config.is_valid.captcha.plugin_id.app_error", nil, "", http.StatusBadRequest)
+		}
+	default:
+		return NewAppError("Config.IsValid", "model.config.is_valid.captcha.provider.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *s.VerifyTimeoutSeconds <= 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.captcha.verify_timeout.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *s.LoginFailuresPerIp < 0 || *s.LoginFailuresPerUser < 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.captcha.login_failures.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if *s.FailureWindowMinutes <= 0 {
+		return NewAppError("Config.IsValid", "model.config.is_valid.captcha.failure_window.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	return nil
+}
+
 func (ls *LocalizationSettings) isValid() *AppError {
 	if len(*ls.AvailableLocales) > 0 {
 		if !strings.Contains(*ls.AvailableLocales, *ls.DefaultClientLocale) {
@@ -2343,6 +3853,7 @@ func (o *Config) Sanitize() {
 	}
 
 	*o.FileSettings.PublicLinkSalt = FAKE_SETTING
+	*o.ServiceSettings.PostActionSigningSecret = FAKE_SETTING
 	if len(o.FileSettings.AmazonS3SecretAccessKey) > 0 {
 		o.FileSettings.AmazonS3SecretAccessKey = FAKE_SETTING
 	}
@@ -2351,12 +3862,19 @@ func (o *Config) Sanitize() {
 	if len(o.EmailSettings.SMTPPassword) > 0 {
 		o.EmailSettings.SMTPPassword = FAKE_SETTING
 	}
+	if len(*o.EmailSettings.BounceWebhookSecret) > 0 {
+		*o.EmailSettings.BounceWebhookSecret = FAKE_SETTING
+	}
 
 	if len(o.GitLabSettings.Secret) > 0 {
 		o.GitLabSettings.Secret = FAKE_SETTING
 	}
 
 	*o.SqlSettings.DataSource = FAKE_SETTING
+
+	if len(*o.SqlSettings.DataSourceFailover) > 0 {
+		*o.SqlSettings.DataSourceFailover = FAKE_SETTING
+	}
 	o.SqlSettings.AtRestEncryptKey = FAKE_SETTING
 
 	for i := range o.SqlSettings.DataSourceReplicas {
@@ -2368,4 +3886,8 @@ func (o *Config) Sanitize() {
 	}
 
 	*o.ElasticsearchSettings.Password = FAKE_SETTING
+
+	if len(*o.CaptchaSettings.SecretKey) > 0 {
+		*o.CaptchaSettings.SecretKey = FAKE_SETTING
+	}
 }
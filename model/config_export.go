@@ -0,0 +1,214 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ConfigChange is a setting that's different between two configs, named by its path like "ServiceSettings.SiteURL".
+type ConfigChange struct {
+	Setting string
+	Old     interface{}
+	New     interface{}
+}
+
+// SanitizeForExport replaces every secret in the config with FAKE_SETTING, including the ones that Sanitize leaves
+// alone because the System Console still needs to show them.
+func (o *Config) SanitizeForExport() {
+	o.Sanitize()
+
+	if len(o.ServiceSettings.GoogleDeveloperKey) > 0 {
+		o.ServiceSettings.GoogleDeveloperKey = FAKE_SETTING
+	}
+
+	if len(o.GoogleSettings.Secret) > 0 {
+		o.GoogleSettings.Secret = FAKE_SETTING
+	}
+
+	if len(o.Office365Settings.Secret) > 0 {
+		o.Office365Settings.Secret = FAKE_SETTING
+	}
+
+	if o.WebrtcSettings.GatewayAdminSecret != nil && len(*o.WebrtcSettings.GatewayAdminSecret) > 0 {
+		*o.WebrtcSettings.GatewayAdminSecret = FAKE_SETTING
+	}
+
+	if o.WebrtcSettings.TurnSharedKey != nil && len(*o.WebrtcSettings.TurnSharedKey) > 0 {
+		*o.WebrtcSettings.TurnSharedKey = FAKE_SETTING
+	}
+
+	if relay := o.MessageExportSettings.GlobalRelaySettings; relay != nil && relay.SmtpPassword != nil && len(*relay.SmtpPassword) > 0 {
+		*relay.SmtpPassword = FAKE_SETTING
+	}
+}
+
+// MergeConfigJson returns a copy of the config with the settings in data applied onto it. Settings that data leaves out
+// are kept, and so are secrets that data has as FAKE_SETTING, so a config exported with SanitizeForExport can be
+// imported without overwriting the secrets of the config that it's imported into. Settings that the config doesn't
+// have are an error.
+func MergeConfigJson(base *Config, data io.Reader) (*Config, *AppError) {
+	var settings map[string]interface{}
+	if err := json.NewDecoder(data).Decode(&settings); err != nil {
+		return nil, NewAppError("MergeConfigJson", "model.config.merge.invalid_json.app_error", nil, err.Error(), http.StatusBadRequest)
+	}
+
+	var merged map[string]interface{}
+	if err := json.Unmarshal([]byte(base.ToJson()), &merged); err != nil {
+		return nil, NewAppError("MergeConfigJson", "model.config.merge.invalid_json.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	if err := mergeConfigSettings(merged, settings, reflect.TypeOf(Config{}), ""); err != nil {
+		return nil, err
+	}
+
+	b, _ := json.Marshal(merged)
+
+	var cfg *Config
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, NewAppError("MergeConfigJson", "model.config.merge.invalid_json.app_error", nil, err.Error(), http.StatusBadRequest)
+	}
+
+	return cfg, nil
+}
+
+func mergeConfigSettings(base map[string]interface{}, settings map[string]interface{}, t reflect.Type, prefix string) *AppError {
+	for key, value := range settings {
+		setting := prefix + key
+
+		field, ok := t.FieldByNameFunc(func(name string) bool { return strings.EqualFold(name, key) })
+		if !ok {
+			return NewAppError("MergeConfigJson", "model.config.merge.unknown_setting.app_error", map[string]interface{}{"Setting": setting}, "", http.StatusBadRequest)
+		}
+
+		if isConfigPlaceholder(value) {
+			continue
+		}
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+
+		values, isObject := value.(map[string]interface{})
+		baseValues, baseIsObject := base[field.Name].(map[string]interface{})
+		if fieldType.Kind() == reflect.Struct && isObject && baseIsObject {
+			if err := mergeConfigSettings(baseValues, values, fieldType, setting+"."); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if fieldType.Kind() == reflect.Struct && isObject {
+			// The section isn't set in the base config, so its settings are merged onto an empty one
+			section := map[string]interface{}{}
+			if err := mergeConfigSettings(section, values, fieldType, setting+"."); err != nil {
+				return err
+			}
+			base[field.Name] = section
+			continue
+		}
+
+		base[field.Name] = value
+	}
+
+	return nil
+}
+
+func isConfigPlaceholder(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return v == FAKE_SETTING
+	case []interface{}:
+		for _, item := range v {
+			if item == FAKE_SETTING {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// DiffConfigs returns the settings that are different between the two configs, sorted by name, other than the
+// ConfigVersion. The values of secrets are replaced with FAKE_SETTING so that the changes can be shown.
+func DiffConfigs(before *Config, after *Config) []*ConfigChange {
+	beforeSettings := flattenConfig(before)
+	afterSettings := flattenConfig(after)
+
+	sanitizedBefore := before.Clone()
+	sanitizedBefore.SanitizeForExport()
+	sanitizedBeforeSettings := flattenConfig(sanitizedBefore)
+
+	sanitizedAfter := after.Clone()
+	sanitizedAfter.SanitizeForExport()
+	sanitizedAfterSettings := flattenConfig(sanitizedAfter)
+
+	names := make(map[string]bool, len(beforeSettings))
+	for name := range beforeSettings {
+		names[name] = true
+	}
+	for name := range afterSettings {
+		names[name] = true
+	}
+
+	var changes []*ConfigChange
+	for name := range names {
+		// The version changes with every save, so it isn't a change to the settings
+		if name == "ConfigVersion" || reflect.DeepEqual(beforeSettings[name], afterSettings[name]) {
+			continue
+		}
+
+		changes = append(changes, &ConfigChange{
+			Setting: name,
+			Old:     sanitizedBeforeSettings[name],
+			New:     sanitizedAfterSettings[name],
+		})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Setting < changes[j].Setting
+	})
+
+	return changes
+}
+
+func (o *ConfigChange) String() string {
+	return fmt.Sprintf("%v: %v -> %v", o.Setting, configValueString(o.Old), configValueString(o.New))
+}
+
+func configValueString(value interface{}) string {
+	if value == nil {
+		return "(not set)"
+	}
+
+	b, _ := json.Marshal(value)
+	return string(b)
+}
+
+func flattenConfig(cfg *Config) map[string]interface{} {
+	var settings map[string]interface{}
+	json.Unmarshal([]byte(cfg.ToJson()), &settings)
+
+	flattened := make(map[string]interface{})
+	flattenConfigSettings(flattened, settings, "")
+
+	return flattened
+}
+
+func flattenConfigSettings(flattened map[string]interface{}, settings map[string]interface{}, prefix string) {
+	for key, value := range settings {
+		if values, ok := value.(map[string]interface{}); ok && len(values) > 0 {
+			flattenConfigSettings(flattened, values, prefix+key+".")
+		} else {
+			flattened[prefix+key] = value
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeConfigJson(t *testing.T) {
+	source := &Config{}
+	source.SetDefaults()
+	*source.ServiceSettings.SiteURL = "https://stage.example.com"
+	*source.TeamSettings.MaxUsersPerTeam = 500
+	*source.SqlSettings.DataSource = "source-datasource"
+	source.EmailSettings.SMTPPassword = "source-password"
+	source.SanitizeForExport()
+
+	target := &Config{}
+	target.SetDefaults()
+	*target.SqlSettings.DataSource = "target-datasource"
+	target.EmailSettings.SMTPPassword = "target-password"
+	*target.FileSettings.PublicLinkSalt = "target-salt"
+
+	t.Run("round trip", func(t *testing.T) {
+		merged, err := MergeConfigJson(target, strings.NewReader(source.ToJson()))
+		require.Nil(t, err)
+
+		assert.Equal(t, "https://stage.example.com", *merged.ServiceSettings.SiteURL)
+		assert.Equal(t, 500, *merged.TeamSettings.MaxUsersPerTeam)
+		assert.Equal(t, "target-datasource", *merged.SqlSettings.DataSource)
+		assert.Equal(t, "target-password", merged.EmailSettings.SMTPPassword)
+		assert.Equal(t, "target-salt", *merged.FileSettings.PublicLinkSalt)
+		assert.Equal(t, target.SqlSettings.AtRestEncryptKey, merged.SqlSettings.AtRestEncryptKey)
+	})
+
+	t.Run("only present settings", func(t *testing.T) {
+		merged, err := MergeConfigJson(target, strings.NewReader(`{"TeamSettings": {"SiteName": "Promoted"}}`))
+		require.Nil(t, err)
+
+		assert.Equal(t, "Promoted", merged.TeamSettings.SiteName)
+		assert.Equal(t, *target.TeamSettings.MaxUsersPerTeam, *merged.TeamSettings.MaxUsersPerTeam)
+		assert.Equal(t, *target.ServiceSettings.SiteURL, *merged.ServiceSettings.SiteURL)
+
+		changes := DiffConfigs(target, merged)
+		require.Len(t, changes, 1)
+		assert.Equal(t, "TeamSettings.SiteName", changes[0].Setting)
+	})
+
+	t.Run("unknown setting", func(t *testing.T) {
+		_, err := MergeConfigJson(target, strings.NewReader(`{"TeamSettings": {"NotASetting": true}}`))
+		require.NotNil(t, err)
+		assert.Equal(t, "model.config.merge.unknown_setting.app_error", err.Id)
+
+		_, err = MergeConfigJson(target, strings.NewReader(`{"NotASection": {}}`))
+		require.NotNil(t, err)
+	})
+
+	t.Run("invalid json", func(t *testing.T) {
+		_, err := MergeConfigJson(target, strings.NewReader(`[`))
+		require.NotNil(t, err)
+		assert.Equal(t, "model.config.merge.invalid_json.app_error", err.Id)
+	})
+}
+
+func TestDiffConfigsHidesSecrets(t *testing.T) {
+	before := &Config{}
+	before.SetDefaults()
+	after := before.Clone()
+	*after.SqlSettings.DataSource = "new-datasource"
+
+	changes := DiffConfigs(before, after)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "SqlSettings.DataSource", changes[0].Setting)
+	assert.Equal(t, FAKE_SETTING, changes[0].New)
+	assert.NotContains(t, changes[0].String(), "new-datasource")
+}
+
+func TestDiffConfigsIgnoresVersion(t *testing.T) {
+	before := &Config{}
+	before.SetDefaults()
+	after := before.Clone()
+	*after.ConfigVersion++
+
+	assert.Empty(t, DiffConfigs(before, after))
+}
@@ -5,7 +5,9 @@ package model
 
 import (
 	"fmt"
+	"net/http"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -55,6 +57,28 @@ func TestConfigDefaults(t *testing.T) {
 	})
 }
 
+func TestConfigConflictJson(t *testing.T) {
+	cfg := &Config{}
+	cfg.SetDefaults()
+	*cfg.ConfigVersion = 3
+
+	conflict := &ConfigConflict{
+		AppError: *NewAppError("SaveConfigFromVersion", "app.admin.save_config.version_conflict.app_error", nil, "", http.StatusConflict),
+		Config:   cfg,
+	}
+	data := conflict.ToJson()
+
+	// clients that don't know about conflicts read them as an error
+	err := AppErrorFromJson(strings.NewReader(data))
+	assert.Equal(t, "app.admin.save_config.version_conflict.app_error", err.Id)
+	assert.Equal(t, http.StatusConflict, err.StatusCode)
+
+	decoded := ConfigConflictFromJson(strings.NewReader(data))
+	require.NotNil(t, decoded)
+	assert.Equal(t, conflict.Id, decoded.Id)
+	assert.Equal(t, int64(3), *decoded.Config.ConfigVersion)
+}
+
 func TestConfigDefaultFileSettingsDirectory(t *testing.T) {
 	c1 := Config{}
 	c1.SetDefaults()
@@ -121,6 +145,62 @@ func TestConfigDefaultServiceSettingsExperimentalGroupUnreadChannels(t *testing.
 	}
 }
 
+func TestServiceSettingsPostTimeLimits(t *testing.T) {
+	c1 := Config{}
+	c1.SetDefaults()
+
+	assert.Equal(t, -1, c1.ServiceSettings.GetPostEditTimeLimit([]string{CHANNEL_USER_ROLE_ID}))
+	assert.Equal(t, -1, c1.ServiceSettings.GetPostDeleteTimeLimit([]string{CHANNEL_USER_ROLE_ID}))
+
+	*c1.ServiceSettings.PostEditTimeLimit = 300
+	c1.ServiceSettings.PostEditTimeLimitByRole = map[string]int{
+		CHANNEL_ADMIN_ROLE_ID: -1,
+		TEAM_ADMIN_ROLE_ID:    3600,
+		SYSTEM_USER_ROLE_ID:   600,
+	}
+	*c1.ServiceSettings.PostDeleteTimeLimit = 0
+	c1.ServiceSettings.PostDeleteTimeLimitByRole = map[string]int{
+		TEAM_ADMIN_ROLE_ID: 60,
+	}
+
+	assert.Equal(t, 300, c1.ServiceSettings.GetPostEditTimeLimit([]string{CHANNEL_USER_ROLE_ID}))
+	assert.Equal(t, 600, c1.ServiceSettings.GetPostEditTimeLimit([]string{SYSTEM_USER_ROLE_ID, CHANNEL_USER_ROLE_ID}))
+	assert.Equal(t, 3600, c1.ServiceSettings.GetPostEditTimeLimit([]string{SYSTEM_USER_ROLE_ID, TEAM_ADMIN_ROLE_ID}))
+	assert.Equal(t, -1, c1.ServiceSettings.GetPostEditTimeLimit([]string{CHANNEL_ADMIN_ROLE_ID, TEAM_ADMIN_ROLE_ID}))
+	assert.Equal(t, -1, c1.ServiceSettings.GetPostEditTimeLimit([]string{TEAM_ADMIN_ROLE_ID, CHANNEL_ADMIN_ROLE_ID, SYSTEM_USER_ROLE_ID}))
+
+	assert.Equal(t, 0, c1.ServiceSettings.GetPostDeleteTimeLimit([]string{SYSTEM_USER_ROLE_ID}))
+	assert.Equal(t, 60, c1.ServiceSettings.GetPostDeleteTimeLimit([]string{SYSTEM_USER_ROLE_ID, TEAM_ADMIN_ROLE_ID}))
+
+	// a role with a shorter limit than the default still overrides it
+	c1.ServiceSettings.PostEditTimeLimitByRole = map[string]int{CHANNEL_USER_ROLE_ID: 0}
+	assert.Equal(t, 0, c1.ServiceSettings.GetPostEditTimeLimit([]string{CHANNEL_USER_ROLE_ID}))
+
+	require.Nil(t, c1.ServiceSettings.isValid())
+
+	c1.ServiceSettings.PostDeleteTimeLimitByRole[CHANNEL_USER_ROLE_ID] = -2
+	assert.NotNil(t, c1.ServiceSettings.isValid())
+}
+
+func TestServiceSettingsIsValidCors(t *testing.T) {
+	c1 := Config{}
+	c1.SetDefaults()
+
+	for _, allowed := range []string{"*", "https://example.com", "https://*.example.com http://localhost:8080"} {
+		*c1.ServiceSettings.AllowCorsFrom = allowed
+		assert.Nil(t, c1.ServiceSettings.isValid(), allowed)
+	}
+
+	for _, allowed := range []string{"https://example.*", "https://a.*.example.com", "* https://*.*.example.com"} {
+		*c1.ServiceSettings.AllowCorsFrom = allowed
+		assert.NotNil(t, c1.ServiceSettings.isValid(), allowed)
+	}
+
+	*c1.ServiceSettings.AllowCorsFrom = ""
+	*c1.ServiceSettings.CorsMaxAge = -1
+	assert.NotNil(t, c1.ServiceSettings.isValid())
+}
+
 func TestMessageExportSettingsIsValidEnableExportNotSet(t *testing.T) {
 	fs := &FileSettings{}
 	mes := &MessageExportSettings{}
@@ -443,3 +523,154 @@ func TestMessageExportSetDefaultsExportDisabledExportFromTimestampNonZero(t *tes
 	require.Equal(t, int64(0), *mes.ExportFromTimestamp)
 	require.Equal(t, 10000, *mes.BatchSize)
 }
+
+func TestSqlSettingsIsValidHealthCheck(t *testing.T) {
+	ss := &SqlSettings{}
+	ss.SetDefaults()
+	require.Nil(t, ss.isValid())
+
+	ss.HealthCheckIntervalSeconds = NewInt(0)
+	require.Nil(t, ss.isValid(), "should allow the health check to be disabled")
+
+	ss.HealthCheckIntervalSeconds = NewInt(-1)
+	require.NotNil(t, ss.isValid())
+
+	ss.HealthCheckIntervalSeconds = NewInt(10)
+	ss.HealthCheckFailureThreshold = NewInt(0)
+	require.NotNil(t, ss.isValid())
+}
+
+func TestCacheSettingsIsValid(t *testing.T) {
+	cs := &CacheSettings{}
+	cs.SetDefaults()
+	require.Nil(t, cs.isValid())
+	require.Equal(t, CHANNEL_CACHE_SIZE, *cs.Caches[CACHE_CHANNEL_BY_NAME].Size)
+
+	cs.Caches[CACHE_CHANNEL_BY_NAME].ExpirySeconds = NewInt(0)
+	require.Nil(t, cs.isValid(), "should allow entries that never expire")
+
+	cs.Caches[CACHE_CHANNEL_BY_NAME].Size = NewInt(0)
+	require.NotNil(t, cs.isValid())
+
+	cs.Caches[CACHE_CHANNEL_BY_NAME].Size = NewInt(100)
+	cs.Caches[CACHE_CHANNEL_BY_NAME].ExpirySeconds = NewInt(-1)
+	require.NotNil(t, cs.isValid())
+
+	cs.Caches[CACHE_CHANNEL_BY_NAME].ExpirySeconds = NewInt(60)
+	cs.Caches["Unknown"] = &CacheSizeSettings{Size: NewInt(1), ExpirySeconds: NewInt(1)}
+	require.NotNil(t, cs.isValid())
+}
+
+func TestServiceSettingsIsValidTrustedProxies(t *testing.T) {
+	ss := &ServiceSettings{}
+	ss.SetDefaults()
+	require.Nil(t, ss.isValid())
+
+	ss.TrustedProxyIPHeader = NewString("")
+	ss.TrustedProxyCIDRs = NewString("")
+	require.Nil(t, ss.isValid(), "should allow proxies to not be trusted")
+
+	ss.TrustedProxyIPHeader = NewString("X-Forwarded-For X-Real-IP")
+	require.NotNil(t, ss.isValid())
+
+	ss.TrustedProxyIPHeader = NewString(HEADER_REAL_IP)
+	ss.TrustedProxyCIDRs = NewString("10.0.0.0/8 10.0.0.1")
+	require.NotNil(t, ss.isValid())
+}
+
+func TestServiceSettingsIsValidSecretScanning(t *testing.T) {
+	ss := &ServiceSettings{}
+	ss.SetDefaults()
+	require.Nil(t, ss.isValid())
+	assert.NotEmpty(t, ss.SecretScanningRules, "should ship with default rules")
+
+	ss.SecretScanningMode = NewString("quarantine")
+	require.NotNil(t, ss.isValid())
+
+	ss.SecretScanningMode = NewString(SECRET_SCANNING_MODE_ALERT)
+	err := ss.isValid()
+	require.NotNil(t, err, "should require an alert channel")
+	assert.Equal(t, "model.config.is_valid.secret_scanning_alert_channel_id.app_error", err.Id)
+
+	ss.SecretScanningAlertChannelId = NewString(NewId())
+	require.Nil(t, ss.isValid())
+
+	ss.SecretScanningRules["broken"] = "secret-("
+	err = ss.isValid()
+	require.NotNil(t, err)
+	assert.Equal(t, "model.config.is_valid.secret_scanning_rules.app_error", err.Id)
+	delete(ss.SecretScanningRules, "broken")
+
+	ss.SecretScanningTimeBudgetMilliseconds = NewInt(0)
+	require.NotNil(t, ss.isValid())
+}
+
+func TestServiceSettingsIsValidChannelFeeds(t *testing.T) {
+	ss := &ServiceSettings{}
+	ss.SetDefaults()
+	require.Nil(t, ss.isValid())
+
+	ss.ChannelFeedPostCount = NewInt(0)
+	err := ss.isValid()
+	require.NotNil(t, err)
+	assert.Equal(t, "model.config.is_valid.channel_feed_post_count.app_error", err.Id)
+
+	ss.ChannelFeedPostCount = NewInt(CHANNEL_FEED_MAX_POST_COUNT + 1)
+	require.NotNil(t, ss.isValid())
+
+	ss.ChannelFeedPostCount = NewInt(CHANNEL_FEED_MAX_POST_COUNT)
+	require.Nil(t, ss.isValid())
+}
+
+func TestUsernamePolicySettingsIsValid(t *testing.T) {
+	s := &UsernamePolicySettings{}
+	s.SetDefaults()
+	require.Nil(t, s.isValid())
+
+	s.UsernamePattern = NewString(`[a-z]+\.[a-z]+`)
+	require.Nil(t, s.isValid())
+
+	s.UsernamePattern = NewString(`[a-z`)
+	err := s.isValid()
+	require.NotNil(t, err)
+	assert.Equal(t, "model.config.is_valid.username_policy.pattern.app_error", err.Id)
+}
+
+func TestCaptchaSettingsIsValid(t *testing.T) {
+	s := &CaptchaSettings{}
+	s.SetDefaults()
+	require.Nil(t, s.isValid())
+
+	*s.Enable = true
+	err := s.isValid()
+	require.NotNil(t, err)
+	assert.Equal(t, "model.config.is_valid.captcha.keys.app_error", err.Id)
+
+	*s.SiteKey = "site"
+	*s.SecretKey = "secret"
+	require.Nil(t, s.isValid())
+
+	*s.VerifyURL = "ftp://example.com"
+	err = s.isValid()
+	require.NotNil(t, err)
+	assert.Equal(t, "model.config.is_valid.captcha.verify_url.app_error", err.Id)
+
+	*s.Provider = CAPTCHA_PROVIDER_PLUGIN
+	err = s.isValid()
+	require.NotNil(t, err)
+	assert.Equal(t, "model.config.is_valid.captcha.plugin_id.app_error", err.Id)
+
+	*s.PluginId = "captcha"
+	require.Nil(t, s.isValid())
+
+	*s.Provider = "unknown"
+	err = s.isValid()
+	require.NotNil(t, err)
+	assert.Equal(t, "model.config.is_valid.captcha.provider.app_error", err.Id)
+
+	*s.Provider = CAPTCHA_PROVIDER_PLUGIN
+	*s.LoginFailuresPerIp = -1
+	err = s.isValid()
+	require.NotNil(t, err)
+	assert.Equal(t, "model.config.is_valid.captcha.login_failures.app_error", err.Id)
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	CONFIGURATION_HISTORY_SOURCE_API     = "api"
+	CONFIGURATION_HISTORY_SOURCE_CLI     = "cli"
+	CONFIGURATION_HISTORY_SOURCE_RESTORE = "restore"
+	CONFIGURATION_HISTORY_SOURCE_SERVER  = "server"
+
+	CONFIGURATION_HISTORY_SUMMARY_MAX_RUNES = 1024
+	CONFIGURATION_HISTORY_CONFIG_MAX_SIZE   = 65535
+)
+
+// ConfigurationHistory is a version of the config as it was saved. Config is the config with its secrets replaced
+// by FAKE_SETTING, and Secrets has their values encrypted with the at rest encryption key, so neither is sent to
+// clients. Summary lists the settings that the save changed.
+type ConfigurationHistory struct {
+	Id       string `json:"id"`
+	CreateAt int64  `json:"create_at"`
+	UserId   string `json:"user_id"`
+	Source   string `json:"source"`
+	Summary  string `json:"summary"`
+	Config   string `json:"-"`
+	Secrets  string `json:"-"`
+}
+
+func (h *ConfigurationHistory) PreSave() {
+	if h.Id == "" {
+		h.Id = NewId()
+	}
+
+	if h.CreateAt == 0 {
+		h.CreateAt = GetMillis()
+	}
+}
+
+func (h *ConfigurationHistory) IsValid() *AppError {
+	if len(h.Id) != 26 {
+		return NewAppError("ConfigurationHistory.IsValid", "model.configuration_history.is_valid.id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if h.CreateAt == 0 {
+		return NewAppError("ConfigurationHistory.IsValid", "model.configuration_history.is_valid.create_at.app_error", nil, "id="+h.Id, http.StatusBadRequest)
+	}
+
+	if h.UserId != "" && len(h.UserId) != 26 {
+		return NewAppError("ConfigurationHistory.IsValid", "model.configuration_history.is_valid.user_id.app_error", nil, "id="+h.Id, http.StatusBadRequest)
+	}
+
+	switch h.Source {
+	case CONFIGURATION_HISTORY_SOURCE_API, CONFIGURATION_HISTORY_SOURCE_CLI, CONFIGURATION_HISTORY_SOURCE_RESTORE, CONFIGURATION_HISTORY_SOURCE_SERVER:
+	default:
+		return NewAppError("ConfigurationHistory.IsValid", "model.configuration_history.is_valid.source.app_error", nil, "id="+h.Id, http.StatusBadRequest)
+	}
+
+	if utf8.RuneCountInString(h.Summary) > CONFIGURATION_HISTORY_SUMMARY_MAX_RUNES {
+		return NewAppError("ConfigurationHistory.IsValid", "model.configuration_history.is_valid.summary.app_error", nil, "id="+h.Id, http.StatusBadRequest)
+	}
+
+	if h.Config == "" || len(h.Config) > CONFIGURATION_HISTORY_CONFIG_MAX_SIZE || len(h.Secrets) > CONFIGURATION_HISTORY_CONFIG_MAX_SIZE {
+		return NewAppError("ConfigurationHistory.IsValid", "model.configuration_history.is_valid.config.app_error", nil, "id="+h.Id, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (h *ConfigurationHistory) ToJson() string {
+	b, _ := json.Marshal(h)
+	return string(b)
+}
+
+func ConfigurationHistoryFromJson(data io.Reader) *ConfigurationHistory {
+	var history *ConfigurationHistory
+	json.NewDecoder(data).Decode(&history)
+	return history
+}
+
+func ConfigurationHistoryListToJson(list []*ConfigurationHistory) string {
+	b, _ := json.Marshal(list)
+	return string(b)
+}
+
+func ConfigurationHistoryListFromJson(data io.Reader) []*ConfigurationHistory {
+	var list []*ConfigurationHistory
+	json.NewDecoder(data).Decode(&list)
+	return list
+}
+
+func ConfigChangesToJson(changes []*ConfigChange) string {
+	b, _ := json.Marshal(changes)
+	return string(b)
+}
+
+func ConfigChangesFromJson(data io.Reader) []*ConfigChange {
+	var changes []*ConfigChange
+	json.NewDecoder(data).Decode(&changes)
+	return changes
+}
+
+// ConfigChangesSummary lists the names of the changed settings, counting the ones that don't fit in a
+// ConfigurationHistory's summary instead of naming them.
+func ConfigChangesSummary(changes []*ConfigChange) string {
+	if len(changes) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(changes))
+	for _, change := range changes {
+		names = append(names, change.Setting)
+	}
+
+	for shown := len(names); shown > 0; shown-- {
+		summary := strings.Join(names[:shown], ", ")
+		if shown < len(names) {
+			summary += fmt.Sprintf(" and %v more", len(names)-shown)
+		}
+
+		if utf8.RuneCountInString(summary) <= CONFIGURATION_HISTORY_SUMMARY_MAX_RUNES {
+			return summary
+		}
+	}
+
+	return fmt.Sprintf("%v settings", len(names))
+}
+
+// SplitConfigSecrets returns a copy of the config with its secrets replaced by FAKE_SETTING, along with the values of
+// those secrets by the paths of their settings. The at rest encryption key is left out, since it's what the secrets
+// are stored with, so a config that's put back together by JoinConfigSecrets has it as FAKE_SETTING.
+func SplitConfigSecrets(cfg *Config) (*Config, map[string]interface{}) {
+	sanitized := cfg.Clone()
+	sanitized.SanitizeForExport()
+
+	settings := flattenConfig(cfg)
+	sanitizedSettings := flattenConfig(sanitized)
+
+	secrets := map[string]interface{}{}
+	for name, value := range settings {
+		if name == "SqlSettings.AtRestEncryptKey" {
+			continue
+		}
+
+		if configValueString(value) != configValueString(sanitizedSettings[name]) {
+			secrets[name] = value
+		}
+	}
+
+	return sanitized, secrets
+}
+
+// JoinConfigSecrets returns a copy of a config from SplitConfigSecrets with its secrets put back.
+func JoinConfigSecrets(sanitized *Config, secrets map[string]interface{}) (*Config, *AppError) {
+	settings := map[string]interface{}{}
+	for name, value := range secrets {
+		section := settings
+		path := strings.Split(name, ".")
+		for _, key := range path[:len(path)-1] {
+			next, ok := section[key].(map[string]interface{})
+			if !ok {
+				next = map[string]interface{}{}
+				section[key] = next
+			}
+			section = next
+		}
+		section[path[len(path)-1]] = value
+	}
+
+	b, _ := json.Marshal(settings)
+	return MergeConfigJson(sanitized, strings.NewReader(string(b)))
+}
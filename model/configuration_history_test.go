@@ -0,0 +1,77 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigurationHistoryIsValid(t *testing.T) {
+	history := &ConfigurationHistory{Source: CONFIGURATION_HISTORY_SOURCE_API, Config: "{}"}
+	history.PreSave()
+	require.Nil(t, history.IsValid())
+
+	history.Source = "other"
+	assert.NotNil(t, history.IsValid())
+	history.Source = CONFIGURATION_HISTORY_SOURCE_CLI
+
+	history.UserId = "invalid"
+	assert.NotNil(t, history.IsValid())
+	history.UserId = NewId()
+	assert.Nil(t, history.IsValid())
+
+	history.Summary = strings.Repeat("a", CONFIGURATION_HISTORY_SUMMARY_MAX_RUNES+1)
+	assert.NotNil(t, history.IsValid())
+	history.Summary = ""
+
+	history.Config = ""
+	assert.NotNil(t, history.IsValid())
+}
+
+func TestConfigChangesSummary(t *testing.T) {
+	assert.Equal(t, "", ConfigChangesSummary(nil))
+
+	changes := []*ConfigChange{{Setting: "ServiceSettings.SiteURL"}, {Setting: "TeamSettings.SiteName"}}
+	assert.Equal(t, "ServiceSettings.SiteURL, TeamSettings.SiteName", ConfigChangesSummary(changes))
+
+	changes = nil
+	for i := 0; i < 100; i++ {
+		changes = append(changes, &ConfigChange{Setting: "ServiceSettings.SomeLongSettingName"})
+	}
+	summary := ConfigChangesSummary(changes)
+	assert.True(t, len(summary) <= CONFIGURATION_HISTORY_SUMMARY_MAX_RUNES)
+	assert.True(t, strings.HasSuffix(summary, " more"), summary)
+}
+
+func TestSplitAndJoinConfigSecrets(t *testing.T) {
+	cfg := &Config{}
+	cfg.SetDefaults()
+	*cfg.SqlSettings.DataSource = "datasource"
+	cfg.SqlSettings.DataSourceReplicas = []string{"replica"}
+	cfg.EmailSettings.SMTPPassword = "smtp-password"
+	cfg.TeamSettings.SiteName = "Site"
+
+	sanitized, secrets := SplitConfigSecrets(cfg)
+	assert.Equal(t, FAKE_SETTING, *sanitized.SqlSettings.DataSource)
+	assert.Equal(t, FAKE_SETTING, sanitized.EmailSettings.SMTPPassword)
+	assert.Equal(t, "Site", sanitized.TeamSettings.SiteName)
+	assert.Equal(t, "datasource", secrets["SqlSettings.DataSource"])
+	assert.Equal(t, "smtp-password", secrets["EmailSettings.SMTPPassword"])
+	assert.NotContains(t, secrets, "TeamSettings.SiteName")
+	assert.NotContains(t, secrets, "SqlSettings.AtRestEncryptKey", "the key that the secrets are encrypted with shouldn't be one of them")
+	assert.NotContains(t, sanitized.ToJson(), "smtp-password")
+
+	joined, err := JoinConfigSecrets(sanitized, secrets)
+	require.Nil(t, err)
+	assert.Equal(t, "datasource", *joined.SqlSettings.DataSource)
+	assert.Equal(t, []string{"replica"}, joined.SqlSettings.DataSourceReplicas)
+	assert.Equal(t, "smtp-password", joined.EmailSettings.SMTPPassword)
+	assert.Equal(t, *cfg.FileSettings.PublicLinkSalt, *joined.FileSettings.PublicLinkSalt)
+	assert.Equal(t, FAKE_SETTING, joined.SqlSettings.AtRestEncryptKey)
+	assert.Equal(t, "Site", joined.TeamSettings.SiteName)
+}
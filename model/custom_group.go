@@ -0,0 +1,172 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	CUSTOM_GROUP_DISPLAY_NAME_MAX_RUNES = 64
+	CUSTOM_GROUP_DESCRIPTION_MAX_RUNES  = 1024
+	CUSTOM_GROUP_AUTOCOMPLETE_LIMIT     = 25
+)
+
+// CustomGroup is a named list of users that can be mentioned all at once with @name.
+type CustomGroup struct {
+	Id          string `json:"id"`
+	CreateAt    int64  `json:"create_at"`
+	UpdateAt    int64  `json:"update_at"`
+	CreatorId   string `json:"creator_id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Description string `json:"description"`
+}
+
+type CustomGroupPatch struct {
+	Name        *string `json:"name"`
+	DisplayName *string `json:"display_name"`
+	Description *string `json:"description"`
+}
+
+type GroupMember struct {
+	GroupId  string `json:"group_id"`
+	UserId   string `json:"user_id"`
+	CreateAt int64  `json:"create_at"`
+}
+
+// IsValidCustomGroupName returns true if the name can be used to mention a group. Group names follow
+// the same rules as usernames so that they can't be mistaken for the special mentions.
+func IsValidCustomGroupName(name string) bool {
+	return IsValidUsername(name) && name != "here"
+}
+
+func (o *CustomGroup) IsValid() *AppError {
+	if len(o.Id) != 26 {
+		return NewAppError("CustomGroup.IsValid", "model.custom_group.is_valid.id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if o.CreateAt == 0 {
+		return NewAppError("CustomGroup.IsValid", "model.custom_group.is_valid.create_at.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	if o.UpdateAt == 0 {
+		return NewAppError("CustomGroup.IsValid", "model.custom_group.is_valid.update_at.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	if len(o.CreatorId) != 26 {
+		return NewAppError("CustomGroup.IsValid", "model.custom_group.is_valid.creator_id.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	if !IsValidCustomGroupName(o.Name) {
+		return NewAppError("CustomGroup.IsValid", "model.custom_group.is_valid.name.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	if o.DisplayName == "" || utf8.RuneCountInString(o.DisplayName) > CUSTOM_GROUP_DISPLAY_NAME_MAX_RUNES {
+		return NewAppError("CustomGroup.IsValid", "model.custom_group.is_valid.display_name.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	if utf8.RuneCountInString(o.Description) > CUSTOM_GROUP_DESCRIPTION_MAX_RUNES {
+		return NewAppError("CustomGroup.IsValid", "model.custom_group.is_valid.description.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (o *CustomGroup) PreSave() {
+	if o.Id == "" {
+		o.Id = NewId()
+	}
+
+	o.Name = strings.ToLower(o.Name)
+
+	o.CreateAt = GetMillis()
+	o.UpdateAt = o.CreateAt
+}
+
+func (o *CustomGroup) PreUpdate() {
+	o.Name = strings.ToLower(o.Name)
+
+	o.UpdateAt = GetMillis()
+}
+
+func (o *CustomGroup) Patch(patch *CustomGroupPatch) {
+	if patch.Name != nil {
+		o.Name = *patch.Name
+	}
+
+	if patch.DisplayName != nil {
+		o.DisplayName = *patch.DisplayName
+	}
+
+	if patch.Description != nil {
+		o.Description = *patch.Description
+	}
+}
+
+func (o *CustomGroup) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func CustomGroupFromJson(data io.Reader) *CustomGroup {
+	var o *CustomGroup
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
+
+func CustomGroupListToJson(groups []*CustomGroup) string {
+	b, _ := json.Marshal(groups)
+	return string(b)
+}
+
+func CustomGroupListFromJson(data io.Reader) []*CustomGroup {
+	var o []*CustomGroup
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
+
+func (o *CustomGroupPatch) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func CustomGroupPatchFromJson(data io.Reader) *CustomGroupPatch {
+	var o *CustomGroupPatch
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
+
+func (o *GroupMember) IsValid() *AppError {
+	if len(o.GroupId) != 26 {
+		return NewAppError("GroupMember.IsValid", "model.group_member.is_valid.group_id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if len(o.UserId) != 26 {
+		return NewAppError("GroupMember.IsValid", "model.group_member.is_valid.user_id.app_error", nil, "group_id="+o.GroupId, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (o *GroupMember) PreSave() {
+	if o.CreateAt == 0 {
+		o.CreateAt = GetMillis()
+	}
+}
+
+func GroupMemberListToJson(members []*GroupMember) string {
+	b, _ := json.Marshal(members)
+	return string(b)
+}
+
+func GroupMemberListFromJson(data io.Reader) []*GroupMember {
+	var o []*GroupMember
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
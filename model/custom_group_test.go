@@ -0,0 +1,99 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomGroupIsValid(t *testing.T) {
+	group := CustomGroup{
+		CreatorId:   NewId(),
+		Name:        "Engineering",
+		DisplayName: "Engineering",
+	}
+	group.PreSave()
+
+	assert.Equal(t, "engineering", group.Name)
+	assert.Nil(t, group.IsValid())
+
+	group.CreatorId = "junk"
+	assert.NotNil(t, group.IsValid())
+
+	group.CreatorId = NewId()
+	group.DisplayName = ""
+	assert.NotNil(t, group.IsValid())
+
+	group.DisplayName = strings.Repeat("ü", CUSTOM_GROUP_DISPLAY_NAME_MAX_RUNES+1)
+	assert.NotNil(t, group.IsValid())
+
+	group.DisplayName = "Engineering"
+	group.Description = strings.Repeat("a", CUSTOM_GROUP_DESCRIPTION_MAX_RUNES+1)
+	assert.NotNil(t, group.IsValid())
+
+	group.Description = ""
+	for _, name := range []string{"", "all", "channel", "here", "front end", "front@end", strings.Repeat("a", USER_NAME_MAX_LENGTH+1)} {
+		group.Name = name
+		assert.NotNil(t, group.IsValid(), name)
+	}
+
+	for _, name := range []string{"frontend", "front-end", "front.end", "front_end", "team1"} {
+		group.Name = name
+		assert.Nil(t, group.IsValid(), name)
+	}
+}
+
+func TestCustomGroupPatch(t *testing.T) {
+	group := CustomGroup{
+		Name:        "frontend",
+		DisplayName: "Frontend",
+		Description: "description",
+	}
+
+	name := "web"
+	group.Patch(&CustomGroupPatch{Name: &name})
+
+	assert.Equal(t, "web", group.Name)
+	assert.Equal(t, "Frontend", group.DisplayName)
+	assert.Equal(t, "description", group.Description)
+}
+
+func TestCustomGroupJson(t *testing.T) {
+	group := &CustomGroup{
+		Id:          NewId(),
+		Name:        "frontend",
+		DisplayName: "Frontend",
+	}
+
+	decoded := CustomGroupFromJson(strings.NewReader(group.ToJson()))
+	require.NotNil(t, decoded)
+	assert.Equal(t, group, decoded)
+
+	groups := CustomGroupListFromJson(strings.NewReader(CustomGroupListToJson([]*CustomGroup{group})))
+	assert.Equal(t, []*CustomGroup{group}, groups)
+
+	assert.Nil(t, CustomGroupFromJson(strings.NewReader("junk")))
+}
+
+func TestGroupMemberIsValid(t *testing.T) {
+	member := GroupMember{
+		GroupId: NewId(),
+		UserId:  NewId(),
+	}
+	member.PreSave()
+
+	assert.NotZero(t, member.CreateAt)
+	assert.Nil(t, member.IsValid())
+
+	member.UserId = "junk"
+	assert.NotNil(t, member.IsValid())
+
+	member.UserId = NewId()
+	member.GroupId = ""
+	assert.NotNil(t, member.IsValid())
+}
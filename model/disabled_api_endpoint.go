@@ -0,0 +1,66 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"net/http"
+	"strings"
+)
+
+// requiredAPIEndpoints can't be disabled since clients can't log in and load balancers can't check on the server
+// without them, keyed by their path with the method that they're requested with.
+var requiredAPIEndpoints = map[string]string{
+	API_URL_SUFFIX + "/system/ping": http.MethodGet,
+	API_URL_SUFFIX + "/users/login": http.MethodPost,
+}
+
+// ParseDisabledAPIEndpoint splits an entry of ServiceSettings.DisabledAPIEndpoints, such as "POST /api/v4/users", into
+// its method and path prefix. The method is "*" if every method is disabled.
+func ParseDisabledAPIEndpoint(endpoint string) (method, path string, ok bool) {
+	fields := strings.Fields(endpoint)
+	if len(fields) != 2 {
+		return "", "", false
+	}
+
+	method = strings.ToUpper(fields[0])
+	switch method {
+	case "*", http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return "", "", false
+	}
+
+	path = strings.TrimRight(fields[1], "/")
+	if !strings.HasPrefix(path, "/") || strings.ContainsAny(path, "?#{}") {
+		return "", "", false
+	}
+
+	return method, path, true
+}
+
+// PathHasAPIPrefix returns true if path is prefix or is below it, on the boundary of a part of the path, so that
+// "/api/v4/users" covers "/api/v4/users/search" but not "/api/v4/users_stats".
+func PathHasAPIPrefix(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// IsAPIEndpointDisabled returns true if a request with the given method and path is to an endpoint that's disabled by
+// DisabledAPIEndpoints. The endpoints that clients can't do without are never disabled.
+func (s *ServiceSettings) IsAPIEndpointDisabled(method, path string) bool {
+	if requiredAPIEndpoints[path] == method {
+		return false
+	}
+
+	for _, endpoint := range s.DisabledAPIEndpoints {
+		disabledMethod, prefix, ok := ParseDisabledAPIEndpoint(endpoint)
+		if !ok {
+			continue
+		}
+
+		if (disabledMethod == "*" || disabledMethod == method) && PathHasAPIPrefix(path, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDisabledAPIEndpoint(t *testing.T) {
+	for endpoint, expected := range map[string][]string{
+		"POST /api/v4/users":        {"POST", "/api/v4/users"},
+		"get /api/v4/users/search/": {"GET", "/api/v4/users/search"},
+		"* /files":                  {"*", "/files"},
+	} {
+		method, path, ok := ParseDisabledAPIEndpoint(endpoint)
+		assert.True(t, ok, endpoint)
+		assert.Equal(t, expected, []string{method, path}, endpoint)
+	}
+
+	for _, endpoint := range []string{
+		"",
+		"/api/v4/users",
+		"POST",
+		"FETCH /api/v4/users",
+		"POST api/v4/users",
+		"POST /",
+		"POST /api/v4/users/{user_id}",
+		"POST /api/v4/users extra",
+	} {
+		_, _, ok := ParseDisabledAPIEndpoint(endpoint)
+		assert.False(t, ok, endpoint)
+	}
+}
+
+func TestIsAPIEndpointDisabled(t *testing.T) {
+	s := &ServiceSettings{DisabledAPIEndpoints: []string{"POST /api/v4/users", "* /api/v4/system", "PUT invalid"}}
+
+	assert.True(t, s.IsAPIEndpointDisabled("POST", "/api/v4/users"))
+	assert.True(t, s.IsAPIEndpointDisabled("POST", "/api/v4/users/search"))
+	assert.False(t, s.IsAPIEndpointDisabled("GET", "/api/v4/users"))
+	assert.False(t, s.IsAPIEndpointDisabled("POST", "/api/v4/users_stats"), "prefixes should only match whole parts of paths")
+	assert.True(t, s.IsAPIEndpointDisabled("GET", "/api/v4/system/logs"))
+	assert.True(t, s.IsAPIEndpointDisabled("DELETE", "/api/v4/system/logs"))
+
+	assert.False(t, s.IsAPIEndpointDisabled("POST", "/api/v4/users/login"), "login should never be disabled")
+	assert.True(t, s.IsAPIEndpointDisabled("POST", "/api/v4/users/login/switch"))
+	assert.False(t, s.IsAPIEndpointDisabled("GET", "/api/v4/system/ping"), "ping should never be disabled")
+}
+
+func TestDisabledAPIEndpointsIsValid(t *testing.T) {
+	c := Config{}
+	c.SetDefaults()
+
+	c.ServiceSettings.DisabledAPIEndpoints = []string{"POST /api/v4/users", "GET /api/v4/system"}
+	assert.Nil(t, c.IsValid())
+
+	c.ServiceSettings.DisabledAPIEndpoints = []string{"POST"}
+	if err := c.IsValid(); assert.NotNil(t, err) {
+		assert.Equal(t, "model.config.is_valid.disabled_api_endpoint.app_error", err.Id)
+	}
+
+	for _, endpoint := range []string{"GET /api/v4/system/ping", "* /api/v4/system/ping/", "POST /api/v4/users/login"} {
+		c.ServiceSettings.DisabledAPIEndpoints = []string{endpoint}
+		if err := c.IsValid(); assert.NotNil(t, err, endpoint) {
+			assert.Equal(t, "model.config.is_valid.disabled_api_endpoint_required.app_error", err.Id)
+		}
+	}
+}
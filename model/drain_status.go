@@ -0,0 +1,35 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// DrainStatus is the progress of draining a server before it's stopped. Once it's draining, the server refuses new
+// websocket connections, tells the users connected to it to reconnect elsewhere a few at a time over WindowSeconds,
+// and stops running jobs once the ones it's running have finished. It's done once every user has been told to
+// reconnect and the jobs have finished.
+type DrainStatus struct {
+	Draining             bool  `json:"draining"`
+	StartedAt            int64 `json:"started_at"`
+	WindowSeconds        int   `json:"window_seconds"`
+	Users                int   `json:"users"`
+	UsersNotified        int   `json:"users_notified"`
+	WebSocketConnections int   `json:"websocket_connections"`
+	JobsFinished         bool  `json:"jobs_finished"`
+	Done                 bool  `json:"done"`
+}
+
+func (o *DrainStatus) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func DrainStatusFromJson(data io.Reader) *DrainStatus {
+	var o *DrainStatus
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
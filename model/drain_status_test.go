@@ -0,0 +1,25 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrainStatusJson(t *testing.T) {
+	status := &DrainStatus{
+		Draining:             true,
+		StartedAt:            GetMillis(),
+		WindowSeconds:        60,
+		Users:                3,
+		UsersNotified:        1,
+		WebSocketConnections: 4,
+	}
+
+	assert.Equal(t, status, DrainStatusFromJson(strings.NewReader(status.ToJson())))
+	assert.Nil(t, DrainStatusFromJson(strings.NewReader("junk")))
+}
@@ -0,0 +1,30 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// EmojiStats is how much a custom emoji has been used as a reaction, as of the last time that the stats were
+// rolled up at UpdateAt. Emoji that haven't been rolled up yet have an UpdateAt of 0.
+type EmojiStats struct {
+	EmojiId    string `json:"emoji_id"`
+	Name       string `json:"name"`
+	UsageCount int64  `json:"usage_count"`
+	LastUsedAt int64  `json:"last_used_at"`
+	UpdateAt   int64  `json:"update_at"`
+}
+
+func EmojiStatsListToJson(l []*EmojiStats) string {
+	b, _ := json.Marshal(l)
+	return string(b)
+}
+
+func EmojiStatsListFromJson(data io.Reader) []*EmojiStats {
+	var l []*EmojiStats
+	json.NewDecoder(data).Decode(&l)
+	return l
+}
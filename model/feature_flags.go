@@ -0,0 +1,111 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+const (
+	// FEATURE_FLAG_CLIENT_CONFIG_PREFIX starts the names of the feature flags in the client config.
+	FEATURE_FLAG_CLIENT_CONFIG_PREFIX = "FeatureFlag"
+)
+
+// FeatureFlags turn changes on for some servers before they're turned on for everyone. They're set like any other
+// setting, and can be overridden through the API without changing the config file. Every flag needs a default in
+// SetDefaults so that servers with no config for it start normally, and can only be a *bool or a *string.
+type FeatureFlags struct {
+	// TestFeature and TestBoolFeature don't do anything. They're used to test the feature flags themselves.
+	TestFeature     *string
+	TestBoolFeature *bool
+}
+
+func (f *FeatureFlags) SetDefaults() {
+	if f.TestFeature == nil {
+		f.TestFeature = NewString("off")
+	}
+
+	if f.TestBoolFeature == nil {
+		f.TestBoolFeature = NewBool(false)
+	}
+}
+
+// ToMap returns the value of every flag that's set, keyed by the name of the flag.
+func (f *FeatureFlags) ToMap() map[string]string {
+	flags := make(map[string]string)
+
+	v := reflect.ValueOf(f).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if field.IsNil() {
+			continue
+		}
+
+		switch value := field.Elem().Interface().(type) {
+		case bool:
+			flags[v.Type().Field(i).Name] = strconv.FormatBool(value)
+		case string:
+			flags[v.Type().Field(i).Name] = value
+		}
+	}
+
+	return flags
+}
+
+// Bool returns the value of a flag that's a *bool, or its default if it isn't set. It's false for names that aren't
+// flags of that type.
+func (f *FeatureFlags) Bool(name string) bool {
+	value, ok := f.value(name).(bool)
+	return ok && value
+}
+
+// String returns the value of a flag that's a *string, or its default if it isn't set. It's empty for names that
+// aren't flags of that type.
+func (f *FeatureFlags) String(name string) string {
+	value, _ := f.value(name).(string)
+	return value
+}
+
+func (f *FeatureFlags) value(name string) interface{} {
+	if !isFeatureFlagName(name) {
+		return nil
+	}
+
+	if field := reflect.ValueOf(f).Elem().FieldByName(name); !field.IsNil() {
+		return field.Elem().Interface()
+	}
+
+	defaults := &FeatureFlags{}
+	defaults.SetDefaults()
+	return reflect.ValueOf(defaults).Elem().FieldByName(name).Elem().Interface()
+}
+
+// Set changes the flag with the given name to a value written the way that ToMap returns it.
+func (f *FeatureFlags) Set(name string, value string) *AppError {
+	field := reflect.ValueOf(f).Elem().FieldByName(name)
+	if !field.IsValid() || !isFeatureFlagName(name) {
+		return NewAppError("FeatureFlags.Set", "model.feature_flags.set.name.app_error", map[string]interface{}{"Name": name}, "", http.StatusBadRequest)
+	}
+
+	switch field.Type().Elem().Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return NewAppError("FeatureFlags.Set", "model.feature_flags.set.bool.app_error", map[string]interface{}{"Name": name}, err.Error(), http.StatusBadRequest)
+		}
+		field.Set(reflect.ValueOf(&b))
+	case reflect.String:
+		field.Set(reflect.ValueOf(&value))
+	}
+
+	return nil
+}
+
+// isFeatureFlagName returns false for names that FieldByName finds but that aren't the names of exported fields.
+func isFeatureFlagName(name string) bool {
+	field, ok := reflect.TypeOf(FeatureFlags{}).FieldByName(name)
+	return ok && field.PkgPath == ""
+}
@@ -0,0 +1,35 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeatureFlags(t *testing.T) {
+	flags := &FeatureFlags{}
+	assert.Empty(t, flags.ToMap())
+	assert.Equal(t, "off", flags.String("TestFeature"), "unset flags should have their defaults")
+	assert.False(t, flags.Bool("TestBoolFeature"))
+
+	flags.SetDefaults()
+	assert.Equal(t, map[string]string{"TestFeature": "off", "TestBoolFeature": "false"}, flags.ToMap())
+
+	require.Nil(t, flags.Set("TestFeature", "on"))
+	require.Nil(t, flags.Set("TestBoolFeature", "true"))
+	assert.Equal(t, "on", flags.String("TestFeature"))
+	assert.True(t, flags.Bool("TestBoolFeature"))
+	assert.Equal(t, map[string]string{"TestFeature": "on", "TestBoolFeature": "true"}, flags.ToMap())
+
+	assert.False(t, flags.Bool("TestFeature"), "flags should only be read as their own type")
+	assert.Empty(t, flags.String("TestBoolFeature"))
+	assert.Empty(t, flags.String("NotAFeature"))
+
+	assert.NotNil(t, flags.Set("TestBoolFeature", "maybe"))
+	assert.NotNil(t, flags.Set("NotAFeature", "true"))
+	assert.True(t, flags.Bool("TestBoolFeature"))
+}
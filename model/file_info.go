@@ -5,6 +5,8 @@ package model
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"image"
 	"image/gif"
@@ -15,10 +17,23 @@ import (
 	"strings"
 )
 
+const (
+	FILE_CONTENT_TYPE_SNIFF_LENGTH = 512
+)
+
+// FILE_GENERIC_CONTENT_TYPES are the types detected for contents that don't say what they are, such as text without
+// markup or containers like zip that many formats are built on, so the extension says more about the file.
+var FILE_GENERIC_CONTENT_TYPES = map[string]bool{
+	"text/plain":               true,
+	"application/octet-stream": true,
+	"application/zip":          true,
+}
+
 type FileInfo struct {
 	Id              string `json:"id"`
 	CreatorId       string `json:"user_id"`
 	PostId          string `json:"post_id,omitempty"`
+	ChannelId       string `json:"channel_id,omitempty"`
 	CreateAt        int64  `json:"create_at"`
 	UpdateAt        int64  `json:"update_at"`
 	DeleteAt        int64  `json:"delete_at"`
@@ -32,6 +47,17 @@ type FileInfo struct {
 	Width           int    `json:"width,omitempty"`
 	Height          int    `json:"height,omitempty"`
 	HasPreviewImage bool   `json:"has_preview_image,omitempty"`
+	HasPreviewText  bool   `json:"has_preview_text,omitempty"`
+	Language        string `json:"language,omitempty"`
+	// MissingAt is when the file was found to be missing from the file store, or 0 if it hasn't been. Clients show a
+	// placeholder for missing files instead of letting them be downloaded.
+	MissingAt int64 `json:"missing_at,omitempty"`
+	// ContentType is the type detected from the start of the file's contents, or empty if the file was uploaded before
+	// it was detected.
+	ContentType string `json:"content_type,omitempty"`
+	// Hash is the hex encoded SHA-256 of the file's contents, or empty if the file was uploaded before it was
+	// hashed. Uploads of a file that's already stored share its Path instead of storing it again.
+	Hash string `json:"-"` // not sent back to the client
 }
 
 func (info *FileInfo) ToJson() string {
@@ -112,15 +138,41 @@ func (o *FileInfo) IsImage() bool {
 	return strings.HasPrefix(o.MimeType, "image")
 }
 
+// DetectContentType returns the content type of the data from its first FILE_CONTENT_TYPE_SNIFF_LENGTH bytes. Data
+// without a recognized signature is either text/plain or application/octet-stream.
+func DetectContentType(data []byte) string {
+	if len(data) > FILE_CONTENT_TYPE_SNIFF_LENGTH {
+		data = data[:FILE_CONTENT_TYPE_SNIFF_LENGTH]
+	}
+
+	return http.DetectContentType(data)
+}
+
+// GetContentType returns the type that the file is served with. The type detected from its contents is used over the
+// one from its extension, unless only a generic type could be detected, so that a file can't be made to render as
+// something other than what it contains by renaming it.
+func (info *FileInfo) GetContentType() string {
+	if info.ContentType != "" {
+		if mediaType, _, err := mime.ParseMediaType(info.ContentType); err == nil && !FILE_GENERIC_CONTENT_TYPES[mediaType] {
+			return info.ContentType
+		}
+	}
+
+	return info.MimeType
+}
+
 func GetInfoForBytes(name string, data []byte) (*FileInfo, *AppError) {
+	hash := sha256.Sum256(data)
 	info := &FileInfo{
 		Name: name,
 		Size: int64(len(data)),
+		Hash: hex.EncodeToString(hash[:]),
 	}
 	var err *AppError
 
 	extension := strings.ToLower(filepath.Ext(name))
 	info.MimeType = mime.TypeByExtension(extension)
+	info.ContentType = DetectContentType(data)
 
 	if extension != "" && extension[0] == '.' {
 		// The client expects a file extension without the leading period
@@ -148,6 +200,13 @@ func GetInfoForBytes(name string, data []byte) (*FileInfo, *AppError) {
 				info.HasPreviewImage = true
 			}
 		}
+	} else if info.IsTextLike() && DetectTextEncoding(data) != "" {
+		// Text files are previewed from their contents, so files that only have the name of one aren't
+		info.HasPreviewText = true
+		info.Language = FILE_TEXT_LANGUAGES[GetFileTextLanguageKey(name)]
+		if info.MimeType == "" {
+			info.MimeType = "text/plain"
+		}
 	}
 
 	return info, err
@@ -0,0 +1,66 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// FileInfoFilter limits a user's files to those matching all of its set fields. Files are matched against the
+// channel and team of the post that they're attached to. Times are in milliseconds, and a zero value leaves that
+// bound open.
+type FileInfoFilter struct {
+	CreatorId string `json:"user_id"`
+	TeamId    string `json:"team_id,omitempty"`
+	ChannelId string `json:"channel_id,omitempty"`
+
+	CreatedAfter  int64 `json:"created_after,omitempty"`
+	CreatedBefore int64 `json:"created_before,omitempty"`
+}
+
+// FileInfoStats sums up the files matching a filter.
+type FileInfoStats struct {
+	TotalCount int64 `json:"total_count"`
+	TotalSize  int64 `json:"total_size"`
+}
+
+// UserFiles is a page of a user's files along with the count and size of all of their files matching the same
+// filter.
+type UserFiles struct {
+	FileInfos []*FileInfo `json:"file_infos"`
+	FileInfoStats
+}
+
+func (f *FileInfoFilter) IsValid() *AppError {
+	if !IsValidId(f.CreatorId) {
+		return NewAppError("FileInfoFilter.IsValid", "model.file_info_filter.is_valid.user_id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if f.TeamId != "" && !IsValidId(f.TeamId) {
+		return NewAppError("FileInfoFilter.IsValid", "model.file_info_filter.is_valid.team_id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if f.ChannelId != "" && !IsValidId(f.ChannelId) {
+		return NewAppError("FileInfoFilter.IsValid", "model.file_info_filter.is_valid.channel_id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if f.CreatedAfter < 0 || f.CreatedBefore < 0 || (f.CreatedBefore != 0 && f.CreatedAfter >= f.CreatedBefore) {
+		return NewAppError("FileInfoFilter.IsValid", "model.file_info_filter.is_valid.created.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (f *UserFiles) ToJson() string {
+	b, _ := json.Marshal(f)
+	return string(b)
+}
+
+func UserFilesFromJson(data io.Reader) *UserFiles {
+	var f *UserFiles
+	json.NewDecoder(data).Decode(&f)
+	return f
+}
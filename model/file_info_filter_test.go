@@ -0,0 +1,43 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileInfoFilterIsValid(t *testing.T) {
+	filter := &FileInfoFilter{CreatorId: NewId(), TeamId: NewId(), ChannelId: NewId(), CreatedAfter: 1, CreatedBefore: 2}
+	require.Nil(t, filter.IsValid())
+
+	for id, change := range map[string]func(*FileInfoFilter){
+		"model.file_info_filter.is_valid.user_id.app_error":    func(f *FileInfoFilter) { f.CreatorId = "" },
+		"model.file_info_filter.is_valid.team_id.app_error":    func(f *FileInfoFilter) { f.TeamId = "junk" },
+		"model.file_info_filter.is_valid.channel_id.app_error": func(f *FileInfoFilter) { f.ChannelId = "junk" },
+		"model.file_info_filter.is_valid.created.app_error":    func(f *FileInfoFilter) { f.CreatedAfter = f.CreatedBefore },
+	} {
+		invalid := *filter
+		change(&invalid)
+
+		err := invalid.IsValid()
+		if assert.NotNil(t, err, id) {
+			assert.Equal(t, id, err.Id)
+		}
+	}
+}
+
+func TestUserFilesJson(t *testing.T) {
+	files := &UserFiles{
+		FileInfos:     []*FileInfo{{Id: NewId(), Name: "file.txt", Size: 10}},
+		FileInfoStats: FileInfoStats{TotalCount: 3, TotalSize: 30},
+	}
+
+	json := files.ToJson()
+	assert.Contains(t, json, `"total_size":30`)
+	assert.Equal(t, files, UserFilesFromJson(strings.NewReader(json)))
+}
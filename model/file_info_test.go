@@ -10,6 +10,9 @@ import (
 	"io/ioutil"
 	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestFileInfoIsValid(t *testing.T) {
@@ -198,3 +201,60 @@ func TestGetInfoForFile(t *testing.T) {
 		t.Fatalf("Got incorrect mime type: %v", info.MimeType)
 	}
 }
+
+func TestFileInfoGetContentType(t *testing.T) {
+	pngFile, err := ioutil.ReadFile("../tests/test.png")
+	require.Nil(t, err)
+
+	htmlFile := []byte("<html><body><script>alert('hello')</script></body></html>")
+
+	for name, testCase := range map[string]struct {
+		Filename            string
+		Data                []byte
+		ExpectedContentType string
+	}{
+		"image":                   {"test.png", pngFile, "image/png"},
+		"image without extension": {"test", pngFile, "image/png"},
+		"html named as an image":  {"image.png", htmlFile, "text/html; charset=utf-8"},
+		"text":                    {"test.txt", []byte("hello"), "text/plain; charset=utf-8"},
+		"text named as an image":  {"image.png", []byte("hello"), "image/png"},
+		"unknown":                 {"test", []byte{0, 1, 2}, ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			info, _ := GetInfoForBytes(testCase.Filename, testCase.Data)
+			assert.Equal(t, DetectContentType(testCase.Data), info.ContentType)
+			assert.Equal(t, testCase.ExpectedContentType, info.GetContentType())
+		})
+	}
+
+	t.Run("zip based format", func(t *testing.T) {
+		info := &FileInfo{MimeType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document", ContentType: "application/zip"}
+		assert.Equal(t, info.MimeType, info.GetContentType())
+	})
+
+	t.Run("not yet detected", func(t *testing.T) {
+		info := &FileInfo{MimeType: "image/png"}
+		assert.Equal(t, "image/png", info.GetContentType())
+	})
+
+	t.Run("only the start is sniffed", func(t *testing.T) {
+		data := append(make([]byte, FILE_CONTENT_TYPE_SNIFF_LENGTH), htmlFile...)
+		assert.Equal(t, "application/octet-stream", DetectContentType(data))
+	})
+}
+
+func TestGetInfoForBytesHash(t *testing.T) {
+	info, err := GetInfoForBytes("file.txt", []byte("hello"))
+	require.Nil(t, err)
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", info.Hash)
+
+	renamed, err := GetInfoForBytes("other.txt", []byte("hello"))
+	require.Nil(t, err)
+	assert.Equal(t, info.Hash, renamed.Hash, "the hash should only depend on the contents")
+
+	changed, err := GetInfoForBytes("file.txt", []byte("hello!"))
+	require.Nil(t, err)
+	assert.NotEqual(t, info.Hash, changed.Hash)
+
+	assert.NotContains(t, info.ToJson(), info.Hash)
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// FilePublicLink is a link that lets anyone download a file without logging in. Its id is the secret in the link's
+// URL, so it can be limited to a number of downloads, expire and be revoked without affecting other links to the
+// same file. A MaxDownloads or ExpiresAt of 0 means that the link has no limit.
+type FilePublicLink struct {
+	Id            string `json:"id"`
+	FileId        string `json:"file_id"`
+	ChannelId     string `json:"channel_id"`
+	CreatorId     string `json:"creator_id"`
+	CreateAt      int64  `json:"create_at"`
+	ExpiresAt     int64  `json:"expires_at"`
+	DeleteAt      int64  `json:"delete_at"`
+	MaxDownloads  int    `json:"max_downloads"`
+	DownloadCount int    `json:"download_count"`
+	// Link is the URL of the link, which is filled in when it's returned to its creator.
+	Link string `json:"link,omitempty" db:"-"`
+}
+
+func (l *FilePublicLink) IsValid() *AppError {
+	if len(l.Id) != 26 {
+		return NewAppError("FilePublicLink.IsValid", "model.file_public_link.is_valid.id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if len(l.FileId) != 26 {
+		return NewAppError("FilePublicLink.IsValid", "model.file_public_link.is_valid.file_id.app_error", nil, "id="+l.Id, http.StatusBadRequest)
+	}
+
+	if len(l.ChannelId) != 26 {
+		return NewAppError("FilePublicLink.IsValid", "model.file_public_link.is_valid.channel_id.app_error", nil, "id="+l.Id, http.StatusBadRequest)
+	}
+
+	if len(l.CreatorId) != 26 {
+		return NewAppError("FilePublicLink.IsValid", "model.file_public_link.is_valid.creator_id.app_error", nil, "id="+l.Id, http.StatusBadRequest)
+	}
+
+	if l.CreateAt == 0 {
+		return NewAppError("FilePublicLink.IsValid", "model.file_public_link.is_valid.create_at.app_error", nil, "id="+l.Id, http.StatusBadRequest)
+	}
+
+	if l.ExpiresAt < 0 || (l.ExpiresAt != 0 && l.ExpiresAt <= l.CreateAt) {
+		return NewAppError("FilePublicLink.IsValid", "model.file_public_link.is_valid.expires_at.app_error", nil, "id="+l.Id, http.StatusBadRequest)
+	}
+
+	if l.MaxDownloads < 0 {
+		return NewAppError("FilePublicLink.IsValid", "model.file_public_link.is_valid.max_downloads.app_error", nil, "id="+l.Id, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (l *FilePublicLink) PreSave() {
+	l.Id = NewId()
+	l.CreateAt = GetMillis()
+	l.DeleteAt = 0
+	l.DownloadCount = 0
+}
+
+// IsUsable returns true if the link hasn't been revoked, expired or used up at the given time, in milliseconds.
+func (l *FilePublicLink) IsUsable(now int64) bool {
+	return l.DeleteAt == 0 &&
+		(l.ExpiresAt == 0 || l.ExpiresAt > now) &&
+		(l.MaxDownloads == 0 || l.DownloadCount < l.MaxDownloads)
+}
+
+// IsUnlimited returns true if the link never expires or runs out of downloads.
+func (l *FilePublicLink) IsUnlimited() bool {
+	return l.ExpiresAt == 0 && l.MaxDownloads == 0
+}
+
+// Url returns the address that the file can be downloaded from with the link.
+func (l *FilePublicLink) Url(siteURL string) string {
+	return fmt.Sprintf("%s/files/%v/public?t=%s", siteURL, l.FileId, l.Id)
+}
+
+func (l *FilePublicLink) ToJson() string {
+	b, _ := json.Marshal(l)
+	return string(b)
+}
+
+func FilePublicLinkFromJson(data io.Reader) *FilePublicLink {
+	var l *FilePublicLink
+	json.NewDecoder(data).Decode(&l)
+	return l
+}
+
+func FilePublicLinkListToJson(l []*FilePublicLink) string {
+	b, _ := json.Marshal(l)
+	return string(b)
+}
+
+func FilePublicLinkListFromJson(data io.Reader) []*FilePublicLink {
+	var l []*FilePublicLink
+	json.NewDecoder(data).Decode(&l)
+	return l
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilePublicLinkIsValid(t *testing.T) {
+	link := &FilePublicLink{FileId: NewId(), ChannelId: NewId(), CreatorId: NewId(), MaxDownloads: 5}
+	link.PreSave()
+	require.Nil(t, link.IsValid())
+
+	for id, change := range map[string]func(*FilePublicLink){
+		"model.file_public_link.is_valid.id.app_error":            func(l *FilePublicLink) { l.Id = "" },
+		"model.file_public_link.is_valid.file_id.app_error":       func(l *FilePublicLink) { l.FileId = "" },
+		"model.file_public_link.is_valid.channel_id.app_error":    func(l *FilePublicLink) { l.ChannelId = "" },
+		"model.file_public_link.is_valid.creator_id.app_error":    func(l *FilePublicLink) { l.CreatorId = "" },
+		"model.file_public_link.is_valid.create_at.app_error":     func(l *FilePublicLink) { l.CreateAt = 0 },
+		"model.file_public_link.is_valid.expires_at.app_error":    func(l *FilePublicLink) { l.ExpiresAt = l.CreateAt },
+		"model.file_public_link.is_valid.max_downloads.app_error": func(l *FilePublicLink) { l.MaxDownloads = -1 },
+	} {
+		invalid := *link
+		change(&invalid)
+
+		err := invalid.IsValid()
+		if assert.NotNil(t, err, id) {
+			assert.Equal(t, id, err.Id)
+		}
+	}
+}
+
+func TestFilePublicLinkIsUsable(t *testing.T) {
+	now := GetMillis()
+
+	assert.True(t, (&FilePublicLink{}).IsUsable(now))
+	assert.True(t, (&FilePublicLink{MaxDownloads: 2, DownloadCount: 1, ExpiresAt: now + 1}).IsUsable(now))
+	assert.False(t, (&FilePublicLink{MaxDownloads: 2, DownloadCount: 2}).IsUsable(now))
+	assert.False(t, (&FilePublicLink{ExpiresAt: now}).IsUsable(now))
+	assert.False(t, (&FilePublicLink{DeleteAt: now}).IsUsable(now))
+
+	assert.True(t, (&FilePublicLink{}).IsUnlimited())
+	assert.False(t, (&FilePublicLink{MaxDownloads: 1}).IsUnlimited())
+	assert.False(t, (&FilePublicLink{ExpiresAt: now}).IsUnlimited())
+}
+
+func TestFilePublicLinkJson(t *testing.T) {
+	link := &FilePublicLink{FileId: NewId(), ChannelId: NewId(), CreatorId: NewId(), MaxDownloads: 3}
+	link.PreSave()
+	link.Link = link.Url("http://localhost:8065")
+
+	assert.Equal(t, "http://localhost:8065/files/"+link.FileId+"/public?t="+link.Id, link.Link)
+	assert.Equal(t, link, FilePublicLinkFromJson(strings.NewReader(link.ToJson())))
+	assert.Equal(t, []*FilePublicLink{link}, FilePublicLinkListFromJson(strings.NewReader(FilePublicLinkListToJson([]*FilePublicLink{link}))))
+}
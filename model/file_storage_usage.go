@@ -0,0 +1,59 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+)
+
+const (
+	FILE_STORAGE_USAGE_TYPE_USER = "user"
+	FILE_STORAGE_USAGE_TYPE_TEAM = "team"
+)
+
+// FileStorageUsage is the number of bytes of files that a user has uploaded, or that have been uploaded to a team's
+// channels, kept up to date as files are uploaded and deleted so that storage quotas can be checked without adding
+// up every file. Exempt users can upload past both their own quota and their team's.
+type FileStorageUsage struct {
+	Id       string `json:"id"`
+	Type     string `json:"type"`
+	Bytes    int64  `json:"bytes"`
+	Exempt   bool   `json:"exempt"`
+	UpdateAt int64  `json:"update_at"`
+}
+
+// FileStorageUsageBatch is the result of checking the usage of a batch of users or teams against their files.
+type FileStorageUsageBatch struct {
+	// LastId is the id of the last user or team in the batch, or empty if there were none left to check.
+	LastId string
+	// Corrected is the number of users or teams in the batch whose usage had drifted and was fixed.
+	Corrected int64
+}
+
+func IsValidFileStorageUsageType(usageType string) bool {
+	return usageType == FILE_STORAGE_USAGE_TYPE_USER || usageType == FILE_STORAGE_USAGE_TYPE_TEAM
+}
+
+func (u *FileStorageUsage) ToJson() string {
+	b, _ := json.Marshal(u)
+	return string(b)
+}
+
+func FileStorageUsageFromJson(data io.Reader) *FileStorageUsage {
+	var u *FileStorageUsage
+	json.NewDecoder(data).Decode(&u)
+	return u
+}
+
+func FileStorageUsageListToJson(l []*FileStorageUsage) string {
+	b, _ := json.Marshal(l)
+	return string(b)
+}
+
+func FileStorageUsageListFromJson(data io.Reader) []*FileStorageUsage {
+	var l []*FileStorageUsage
+	json.NewDecoder(data).Decode(&l)
+	return l
+}
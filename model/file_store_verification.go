@@ -0,0 +1,61 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// FILE_STORE_VERIFICATION_MAX_REPORTED is how many of the missing files and dangling objects are listed in a
+// verification's report. All of them are counted.
+const FILE_STORE_VERIFICATION_MAX_REPORTED = 100
+
+// FileStoreVerificationResult is what a verification of the file store against the file infos has found so far, and
+// what it's repaired if it was run with repair.
+type FileStoreVerificationResult struct {
+	Repair bool `json:"repair"`
+	// FilesChecked is how many file infos have been checked for their file, and FilesMissing how many of those don't
+	// have one in the file store.
+	FilesChecked int64 `json:"files_checked"`
+	FilesMissing int64 `json:"files_missing"`
+	// ObjectsChecked is how many objects in the file store have been checked for a file info, and ObjectsDangling how
+	// many of those aren't the file, thumbnail or preview of any.
+	ObjectsChecked  int64 `json:"objects_checked"`
+	ObjectsDangling int64 `json:"objects_dangling"`
+	// FilesFound is how many of the files that had been marked as missing are in the file store again. With repair,
+	// FilesMarked is how many of the missing files have been marked as missing, the files that were found again are
+	// unmarked, and ObjectsDeleted is how many of the dangling objects have been deleted.
+	FilesMarked    int64 `json:"files_marked"`
+	FilesFound     int64 `json:"files_found"`
+	ObjectsDeleted int64 `json:"objects_deleted"`
+	// MissingFileIds and DanglingPaths are the first FILE_STORE_VERIFICATION_MAX_REPORTED of them.
+	MissingFileIds []string `json:"missing_file_ids"`
+	DanglingPaths  []string `json:"dangling_paths"`
+}
+
+func (r *FileStoreVerificationResult) ToJson() string {
+	b, _ := json.Marshal(r)
+	return string(b)
+}
+
+func (r *FileStoreVerificationResult) String() string {
+	lines := []string{
+		fmt.Sprintf("%v files checked: %v missing, %v found again", r.FilesChecked, r.FilesMissing, r.FilesFound),
+		fmt.Sprintf("%v objects checked: %v dangling", r.ObjectsChecked, r.ObjectsDangling),
+	}
+	if r.Repair {
+		lines = append(lines, fmt.Sprintf("%v missing files marked, %v dangling objects deleted", r.FilesMarked, r.ObjectsDeleted))
+	}
+
+	for _, fileId := range r.MissingFileIds {
+		lines = append(lines, "Missing file: "+fileId)
+	}
+	for _, path := range r.DanglingPaths {
+		lines = append(lines, "Dangling object: "+path)
+	}
+
+	return strings.Join(lines, "\n")
+}
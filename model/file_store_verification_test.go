@@ -0,0 +1,31 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStoreVerificationResultString(t *testing.T) {
+	result := &FileStoreVerificationResult{
+		FilesChecked:    10,
+		FilesMissing:    1,
+		ObjectsChecked:  20,
+		ObjectsDangling: 1,
+		MissingFileIds:  []string{"fileid"},
+		DanglingPaths:   []string{"20180101/dangling.txt"},
+	}
+
+	assert.Equal(t, `10 files checked: 1 missing, 0 found again
+20 objects checked: 1 dangling
+Missing file: fileid
+Dangling object: 20180101/dangling.txt`, result.String())
+
+	result.Repair = true
+	result.FilesMarked = 1
+	result.ObjectsDeleted = 1
+	assert.Contains(t, result.String(), "1 missing files marked, 1 dangling objects deleted")
+}
@@ -0,0 +1,254 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"html"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	// Only the start of a file is looked at to decide whether it's text.
+	FILE_TEXT_DETECTION_SIZE = 8 * 1024
+
+	// Text previews show at most this much of a file.
+	FILE_TEXT_PREVIEW_MAX_SIZE = 64 * 1024
+
+	FILE_TEXT_ENCODING_UTF8    = "utf-8"
+	FILE_TEXT_ENCODING_UTF16LE = "utf-16le"
+	FILE_TEXT_ENCODING_UTF16BE = "utf-16be"
+)
+
+var (
+	// FILE_TEXT_LANGUAGES gives the syntax highlighting language for files with each extension, or with each name for
+	// files that are usually named without one.
+	FILE_TEXT_LANGUAGES = map[string]string{
+		".bash": "bash", ".c": "c", ".cc": "cpp", ".cpp": "cpp", ".cs": "csharp", ".css": "css", ".cxx": "cpp",
+		".diff": "diff", ".go": "go", ".h": "c", ".hpp": "cpp", ".htm": "html", ".html": "html", ".ini": "ini",
+		".java": "java", ".js": "javascript", ".json": "json", ".jsx": "javascript", ".kt": "kotlin", ".less": "less",
+		".lua": "lua", ".m": "objectivec", ".md": "markdown", ".patch": "diff", ".php": "php", ".pl": "perl",
+		".ps1": "powershell", ".py": "python", ".r": "r", ".rb": "ruby", ".rs": "rust", ".scala": "scala",
+		".scss": "scss", ".sh": "bash", ".sql": "sql", ".swift": "swift", ".toml": "ini", ".ts": "typescript",
+		".tsx": "typescript", ".xml": "xml", ".yaml": "yaml", ".yml": "yaml", ".zsh": "bash",
+		"dockerfile": "dockerfile", "makefile": "makefile",
+	}
+
+	// FILE_TEXT_EXTENSIONS are the extensions of text files that don't have a syntax highlighting language.
+	FILE_TEXT_EXTENSIONS = []string{".txt", ".log", ".csv", ".tsv", ".conf", ".cfg"}
+
+	FILE_TEXT_MIME_TYPES = []string{"text/", "application/json", "application/xml", "application/javascript", "application/x-sh"}
+)
+
+// FileTextPreview is the start of a text file, escaped so that it can be shown as HTML.
+type FileTextPreview struct {
+	FileId    string `json:"file_id"`
+	Html      string `json:"html"`
+	Language  string `json:"language,omitempty"`
+	Encoding  string `json:"encoding"`
+	Truncated bool   `json:"truncated"`
+}
+
+func (o *FileTextPreview) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func FileTextPreviewFromJson(data io.Reader) *FileTextPreview {
+	var o *FileTextPreview
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
+
+// IsTextLike returns true if the file's name or MIME type suggests that it's text. DetectTextEncoding still needs
+// to be checked to know that it is.
+func (o *FileInfo) IsTextLike() bool {
+	if _, ok := FILE_TEXT_LANGUAGES[GetFileTextLanguageKey(o.Name)]; ok {
+		return true
+	}
+
+	extension := strings.ToLower(filepath.Ext(o.Name))
+	for _, textExtension := range FILE_TEXT_EXTENSIONS {
+		if extension == textExtension {
+			return true
+		}
+	}
+
+	for _, mimeType := range FILE_TEXT_MIME_TYPES {
+		if strings.HasPrefix(o.MimeType, mimeType) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetFileTextLanguageKey returns the key to look up the file in FILE_TEXT_LANGUAGES with.
+func GetFileTextLanguageKey(name string) string {
+	if extension := strings.ToLower(filepath.Ext(name)); extension != "" {
+		return extension
+	}
+
+	return strings.ToLower(filepath.Base(name))
+}
+
+// DetectTextEncoding returns the encoding of the text in data, or an empty string if it isn't text. Only UTF-8 and
+// UTF-16 are recognized, and only the start of data is checked.
+func DetectTextEncoding(data []byte) string {
+	sample := data
+	if len(sample) > FILE_TEXT_DETECTION_SIZE {
+		sample = sample[:FILE_TEXT_DETECTION_SIZE]
+	}
+
+	switch {
+	case bytes.HasPrefix(sample, []byte{0xEF, 0xBB, 0xBF}):
+		if isTextSample(sample[3:], len(sample) < len(data)) {
+			return FILE_TEXT_ENCODING_UTF8
+		}
+		return ""
+	case bytes.HasPrefix(sample, []byte{0xFF, 0xFE}):
+		return detectUtf16(sample[2:], FILE_TEXT_ENCODING_UTF16LE)
+	case bytes.HasPrefix(sample, []byte{0xFE, 0xFF}):
+		return detectUtf16(sample[2:], FILE_TEXT_ENCODING_UTF16BE)
+	}
+
+	// UTF-16 without a byte order mark is recognized by the zero bytes in the characters that are also in ASCII
+	if len(sample) >= 2 {
+		var evenZeros, oddZeros int
+		for i, b := range sample {
+			if b == 0 && i%2 == 0 {
+				evenZeros++
+			} else if b == 0 {
+				oddZeros++
+			}
+		}
+
+		pairs := len(sample) / 2
+		if oddZeros > pairs*9/10 && evenZeros == 0 {
+			return detectUtf16(sample, FILE_TEXT_ENCODING_UTF16LE)
+		} else if evenZeros > pairs*9/10 && oddZeros == 0 {
+			return detectUtf16(sample, FILE_TEXT_ENCODING_UTF16BE)
+		}
+	}
+
+	if isTextSample(sample, len(sample) < len(data)) {
+		return FILE_TEXT_ENCODING_UTF8
+	}
+
+	return ""
+}
+
+func detectUtf16(sample []byte, encoding string) string {
+	if text, ok := decodeUtf16(sample, encoding); ok && isTextSample([]byte(text), false) {
+		return encoding
+	}
+
+	return ""
+}
+
+// isTextSample returns true if the sample is valid UTF-8 without any of the control characters that are only found in
+// binary files. The last character is allowed to be incomplete if the sample was cut from a longer file.
+func isTextSample(sample []byte, truncated bool) bool {
+	if truncated {
+		sample = trimIncompleteRune(sample)
+	}
+
+	if !utf8.Valid(sample) {
+		return false
+	}
+
+	for _, b := range sample {
+		if b < 0x20 && b != '\t' && b != '\n' && b != '\r' && b != '\f' && b != 0x1B {
+			return false
+		}
+	}
+
+	return true
+}
+
+// trimIncompleteRune removes the start of a UTF-8 character that was cut off at the end of data.
+func trimIncompleteRune(data []byte) []byte {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return data[:i]
+			}
+			break
+		}
+	}
+
+	return data
+}
+
+// decodeUtf16 converts UTF-16 to a string, ignoring a final byte or surrogate that was cut off. It returns false if
+// the text has surrogates that don't pair up anywhere else.
+func decodeUtf16(data []byte, encoding string) (string, bool) {
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		if encoding == FILE_TEXT_ENCODING_UTF16BE {
+			units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+		} else {
+			units = append(units, uint16(data[i+1])<<8|uint16(data[i]))
+		}
+	}
+
+	if len(units) > 0 && utf16.IsSurrogate(rune(units[len(units)-1])) && units[len(units)-1] < 0xDC00 {
+		units = units[:len(units)-1]
+	}
+
+	runes := utf16.Decode(units)
+	for _, r := range runes {
+		if r == utf8.RuneError {
+			return "", false
+		}
+	}
+
+	return string(runes), true
+}
+
+// NewFileTextPreview decodes the start of a text file and escapes it. It returns nil if the file isn't text.
+func NewFileTextPreview(info *FileInfo, data []byte) *FileTextPreview {
+	encoding := DetectTextEncoding(data)
+	if encoding == "" {
+		return nil
+	}
+
+	preview := &FileTextPreview{
+		FileId:   info.Id,
+		Language: info.Language,
+		Encoding: encoding,
+	}
+
+	if len(data) > FILE_TEXT_PREVIEW_MAX_SIZE {
+		data = data[:FILE_TEXT_PREVIEW_MAX_SIZE]
+		preview.Truncated = true
+	}
+
+	var text string
+	if encoding == FILE_TEXT_ENCODING_UTF8 {
+		data = bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})
+		if preview.Truncated {
+			data = trimIncompleteRune(data)
+		}
+		text = string(data)
+	} else {
+		if bytes.HasPrefix(data, []byte{0xFF, 0xFE}) || bytes.HasPrefix(data, []byte{0xFE, 0xFF}) {
+			data = data[2:]
+		}
+
+		var ok bool
+		if text, ok = decodeUtf16(data, encoding); !ok {
+			return nil
+		}
+	}
+
+	preview.Html = html.EscapeString(text)
+
+	return preview
+}
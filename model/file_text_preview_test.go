@@ -0,0 +1,130 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeUtf16(text string, bigEndian bool, byteOrderMark bool) []byte {
+	units := utf16.Encode([]rune(text))
+	if byteOrderMark {
+		units = append([]uint16{0xFEFF}, units...)
+	}
+
+	var data []byte
+	for _, unit := range units {
+		if bigEndian {
+			data = append(data, byte(unit>>8), byte(unit))
+		} else {
+			data = append(data, byte(unit), byte(unit>>8))
+		}
+	}
+
+	return data
+}
+
+func TestDetectTextEncoding(t *testing.T) {
+	pngFile, err := ioutil.ReadFile("../tests/test.png")
+	require.Nil(t, err)
+
+	for name, tc := range map[string]struct {
+		data     []byte
+		encoding string
+	}{
+		"ascii":               {[]byte("package main\n\nfunc main() {}\n"), FILE_TEXT_ENCODING_UTF8},
+		"utf-8":               {[]byte("naïve café 🙂\r\n\tindented"), FILE_TEXT_ENCODING_UTF8},
+		"utf-8 with bom":      {append([]byte{0xEF, 0xBB, 0xBF}, "text"...), FILE_TEXT_ENCODING_UTF8},
+		"utf-16le with bom":   {encodeUtf16("hello wörld 🙂", false, true), FILE_TEXT_ENCODING_UTF16LE},
+		"utf-16be with bom":   {encodeUtf16("hello wörld 🙂", true, true), FILE_TEXT_ENCODING_UTF16BE},
+		"utf-16le":            {encodeUtf16("hello world", false, false), FILE_TEXT_ENCODING_UTF16LE},
+		"utf-16be":            {encodeUtf16("hello world", true, false), FILE_TEXT_ENCODING_UTF16BE},
+		"empty":               {[]byte{}, FILE_TEXT_ENCODING_UTF8},
+		"png":                 {pngFile, ""},
+		"zeros":               {make([]byte, 1000), ""},
+		"invalid utf-8":       {[]byte{'a', 0xC3, 0x28, 'b'}, ""},
+		"control characters":  {[]byte("text\x01\x02\x03"), ""},
+		"utf-16 with nulls":   {encodeUtf16("a\x00b", false, true), ""},
+		"cut off utf-8 start": {append(bytes.Repeat([]byte("a"), FILE_TEXT_DETECTION_SIZE-1), "é"...), FILE_TEXT_ENCODING_UTF8},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.encoding, DetectTextEncoding(tc.data))
+		})
+	}
+}
+
+func TestGetInfoForTextFile(t *testing.T) {
+	info, err := GetInfoForBytes("main.go", []byte("package main\n"))
+	require.Nil(t, err)
+	assert.True(t, info.HasPreviewText)
+	assert.Equal(t, "go", info.Language)
+
+	info, err = GetInfoForBytes("notes.txt", []byte("some notes"))
+	require.Nil(t, err)
+	assert.True(t, info.HasPreviewText)
+	assert.Empty(t, info.Language)
+
+	info, err = GetInfoForBytes("Makefile", []byte("all:\n\tgo build\n"))
+	require.Nil(t, err)
+	assert.True(t, info.HasPreviewText)
+	assert.Equal(t, "makefile", info.Language)
+	assert.Equal(t, "text/plain", info.MimeType, "text files should have a MIME type even without an extension")
+
+	pngFile, appErr := ioutil.ReadFile("../tests/test.png")
+	require.Nil(t, appErr)
+	info, err = GetInfoForBytes("definitely-text.txt", pngFile)
+	require.Nil(t, err)
+	assert.False(t, info.HasPreviewText, "binary files named like text files shouldn't be previewed")
+
+	info, err = GetInfoForBytes("archive.zip", []byte("not checked"))
+	require.Nil(t, err)
+	assert.False(t, info.HasPreviewText, "files that aren't named like text files shouldn't be previewed")
+}
+
+func TestNewFileTextPreview(t *testing.T) {
+	info := &FileInfo{Id: NewId(), Language: "html"}
+
+	t.Run("escaped", func(t *testing.T) {
+		preview := NewFileTextPreview(info, []byte(`<script>alert("hi")</script> & more`))
+		require.NotNil(t, preview)
+		assert.Equal(t, "&lt;script&gt;alert(&#34;hi&#34;)&lt;/script&gt; &amp; more", preview.Html)
+		assert.Equal(t, "html", preview.Language)
+		assert.Equal(t, info.Id, preview.FileId)
+		assert.False(t, preview.Truncated)
+	})
+
+	t.Run("utf-16", func(t *testing.T) {
+		preview := NewFileTextPreview(info, encodeUtf16("x < y 🙂", false, true))
+		require.NotNil(t, preview)
+		assert.Equal(t, FILE_TEXT_ENCODING_UTF16LE, preview.Encoding)
+		assert.Equal(t, "x &lt; y 🙂", preview.Html)
+	})
+
+	t.Run("size cap", func(t *testing.T) {
+		// The cap falls in the middle of the last character
+		data := append(bytes.Repeat([]byte("a"), FILE_TEXT_PREVIEW_MAX_SIZE-1), "éééé"...)
+
+		preview := NewFileTextPreview(info, data)
+		require.NotNil(t, preview)
+		assert.True(t, preview.Truncated)
+		assert.Equal(t, strings.Repeat("a", FILE_TEXT_PREVIEW_MAX_SIZE-1), preview.Html)
+
+		preview = NewFileTextPreview(info, encodeUtf16(strings.Repeat("🙂", FILE_TEXT_PREVIEW_MAX_SIZE), true, true))
+		require.NotNil(t, preview)
+		assert.True(t, preview.Truncated)
+		// The byte order mark takes the place of half of the last character, which is dropped
+		assert.Equal(t, strings.Repeat("🙂", FILE_TEXT_PREVIEW_MAX_SIZE/4-1), preview.Html)
+	})
+
+	t.Run("binary", func(t *testing.T) {
+		assert.Nil(t, NewFileTextPreview(info, []byte{0x89, 'P', 'N', 'G', 0x0D, 0x0A, 0x1A, 0x0A, 0x00}))
+	})
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuestsInviteIsValid(t *testing.T) {
+	invite := &GuestsInvite{
+		Emails:   []string{"Guest@example.com"},
+		Channels: []string{NewId()},
+	}
+	assert.Nil(t, invite.IsValid())
+
+	invite.Emails = []string{}
+	assert.NotNil(t, invite.IsValid())
+
+	invite.Emails = []string{"junk"}
+	assert.NotNil(t, invite.IsValid())
+
+	invite.Emails = []string{"guest@example.com"}
+	invite.Channels = []string{}
+	assert.NotNil(t, invite.IsValid())
+
+	invite.Channels = []string{"junk"}
+	assert.NotNil(t, invite.IsValid())
+}
+
+func TestGuestsInviteJson(t *testing.T) {
+	invite := &GuestsInvite{
+		Emails:   []string{"guest@example.com"},
+		Channels: []string{NewId()},
+		Message:  "welcome",
+	}
+
+	decoded := GuestsInviteFromJson(strings.NewReader(invite.ToJson()))
+	require.NotNil(t, decoded)
+	assert.Equal(t, invite, decoded)
+
+	assert.Nil(t, GuestsInviteFromJson(strings.NewReader("junk")))
+}
@@ -0,0 +1,13 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+// IdleDeactivationResult is what a run of idle user deactivation did, or would have done if it was a dry run.
+type IdleDeactivationResult struct {
+	DryRun             bool     `json:"dry_run"`
+	IdleBefore         int64    `json:"idle_before"`
+	WarnedUserIds      []string `json:"warned_user_ids"`
+	DeactivatedUserIds []string `json:"deactivated_user_ids"`
+	ExemptCount        int      `json:"exempt_count"`
+}
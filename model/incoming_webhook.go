@@ -13,6 +13,8 @@ import (
 
 const (
 	DEFAULT_WEBHOOK_USERNAME = "webhook"
+
+	INCOMING_WEBHOOK_ALLOWED_CHANNEL_IDS_MAX_LENGTH = 1024
 )
 
 type IncomingWebhook struct {
@@ -28,6 +30,9 @@ type IncomingWebhook struct {
 	Username      string `json:"username"`
 	IconURL       string `json:"icon_url"`
 	ChannelLocked bool   `json:"channel_locked"`
+	// AllowedChannelIds are the channels, besides ChannelId, that posts can be sent to by overriding the channel.
+	// Posts can be sent to any channel when it's empty, unless ChannelLocked is set.
+	AllowedChannelIds StringArray `json:"allowed_channel_ids"`
 }
 
 type IncomingWebhookRequest struct {
@@ -38,6 +43,11 @@ type IncomingWebhookRequest struct {
 	Props       StringInterface    `json:"props"`
 	Attachments []*SlackAttachment `json:"attachments"`
 	Type        string             `json:"type"`
+
+	// UpdatePostId and DeletePostId are a post that was created by the same webhook to update with the rest of the
+	// request or to delete instead of creating a new one.
+	UpdatePostId string `json:"update_post_id"`
+	DeletePostId string `json:"delete_post_id"`
 }
 
 func (o *IncomingWebhook) ToJson() string {
@@ -105,6 +115,16 @@ func (o *IncomingWebhook) IsValid() *AppError {
 		return NewAppError("IncomingWebhook.IsValid", "model.incoming_hook.icon_url.app_error", nil, "", http.StatusBadRequest)
 	}
 
+	if len(ArrayToJson(o.AllowedChannelIds)) > INCOMING_WEBHOOK_ALLOWED_CHANNEL_IDS_MAX_LENGTH {
+		return NewAppError("IncomingWebhook.IsValid", "model.incoming_hook.allowed_channel_ids.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	for _, channelId := range o.AllowedChannelIds {
+		if len(channelId) != 26 {
+			return NewAppError("IncomingWebhook.IsValid", "model.incoming_hook.allowed_channel_ids.app_error", nil, "", http.StatusBadRequest)
+		}
+	}
+
 	return nil
 }
 
@@ -214,3 +234,26 @@ func (o *IncomingWebhookRequest) ToJson() string {
 		return string(b)
 	}
 }
+
+// IsChannelAllowed returns true if posts can be sent to the channel with the webhook.
+func (o *IncomingWebhook) IsChannelAllowed(channelId string) bool {
+	if channelId == o.ChannelId {
+		return true
+	}
+
+	if o.ChannelLocked {
+		return false
+	}
+
+	if len(o.AllowedChannelIds) == 0 {
+		return true
+	}
+
+	for _, allowed := range o.AllowedChannelIds {
+		if allowed == channelId {
+			return true
+		}
+	}
+
+	return false
+}
@@ -109,6 +109,47 @@ func TestIncomingWebhookIsValid(t *testing.T) {
 	if err := o.IsValid(); err != nil {
 		t.Fatal(err)
 	}
+
+	o.AllowedChannelIds = StringArray{"junk"}
+	if err := o.IsValid(); err == nil {
+		t.Fatal("should be invalid")
+	}
+
+	o.AllowedChannelIds = make(StringArray, 40)
+	for i := range o.AllowedChannelIds {
+		o.AllowedChannelIds[i] = NewId()
+	}
+	if err := o.IsValid(); err == nil {
+		t.Fatal("should be invalid")
+	}
+
+	o.AllowedChannelIds = StringArray{NewId(), NewId()}
+	if err := o.IsValid(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIncomingWebhookIsChannelAllowed(t *testing.T) {
+	allowedChannelId := NewId()
+	otherChannelId := NewId()
+
+	o := IncomingWebhook{ChannelId: NewId()}
+	if !o.IsChannelAllowed(o.ChannelId) || !o.IsChannelAllowed(otherChannelId) {
+		t.Fatal("should allow any channel without an allowlist")
+	}
+
+	o.AllowedChannelIds = StringArray{allowedChannelId}
+	if !o.IsChannelAllowed(o.ChannelId) || !o.IsChannelAllowed(allowedChannelId) {
+		t.Fatal("should allow the webhook's channel and the allowed channels")
+	}
+	if o.IsChannelAllowed(otherChannelId) {
+		t.Fatal("shouldn't allow channels that aren't in the allowlist")
+	}
+
+	o.ChannelLocked = true
+	if o.IsChannelAllowed(allowedChannelId) {
+		t.Fatal("should only allow the webhook's channel when it's locked")
+	}
 }
 
 func TestIncomingWebhookPreSave(t *testing.T) {
@@ -0,0 +1,99 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	HEADER_INTEGRATION_SIGNATURE           = "X-Mattermost-Signature"
+	HEADER_INTEGRATION_SIGNATURE_TIMESTAMP = "X-Mattermost-Signature-Timestamp"
+
+	INTEGRATION_SIGNING_ALGORITHM_SHA256 = "sha256"
+	INTEGRATION_SIGNING_ALGORITHM_SHA512 = "sha512"
+
+	// INTEGRATION_SIGNING_DEFAULT_TOLERANCE is how long, in milliseconds, integrations are suggested to accept a
+	// signed request for after it's sent.
+	INTEGRATION_SIGNING_DEFAULT_TOLERANCE = 5 * 60 * 1000
+
+	INTEGRATION_SIGNING_SECRET_LENGTH = 32
+)
+
+// IsValidIntegrationSigningAlgorithm returns true if requests can be signed with the algorithm. An empty algorithm
+// uses SHA-256.
+func IsValidIntegrationSigningAlgorithm(algorithm string) bool {
+	switch algorithm {
+	case "", INTEGRATION_SIGNING_ALGORITHM_SHA256, INTEGRATION_SIGNING_ALGORITHM_SHA512:
+		return true
+	default:
+		return false
+	}
+}
+
+// SignIntegrationRequest returns the signature sent in the X-Mattermost-Signature header of a request to an outgoing
+// webhook or slash command. It's the algorithm's name, "=" and the hex encoded HMAC, keyed by the integration's
+// signing secret, of the timestamp sent in the X-Mattermost-Signature-Timestamp header, "." and the body of the
+// request, or its query string for slash commands that use GET.
+func SignIntegrationRequest(algorithm, secret string, timestamp int64, body []byte) string {
+	var newHash func() hash.Hash
+	switch algorithm {
+	case INTEGRATION_SIGNING_ALGORITHM_SHA512:
+		newHash = sha512.New
+	default:
+		algorithm = INTEGRATION_SIGNING_ALGORITHM_SHA256
+		newHash = sha256.New
+	}
+
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+
+	return algorithm + "=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SetIntegrationSignatureHeaders signs a request to an outgoing webhook or slash command with the integration's
+// secret, sent at timestamp in milliseconds. Nothing is set for integrations without a signing secret.
+func SetIntegrationSignatureHeaders(header http.Header, algorithm, secret string, timestamp int64, body []byte) {
+	if secret == "" {
+		return
+	}
+
+	header.Set(HEADER_INTEGRATION_SIGNATURE_TIMESTAMP, strconv.FormatInt(timestamp, 10))
+	header.Set(HEADER_INTEGRATION_SIGNATURE, SignIntegrationRequest(algorithm, secret, timestamp, body))
+}
+
+// VerifyIntegrationRequest is used by integrations to check that a request from an outgoing webhook or slash command
+// was signed with their secret and was sent no more than tolerance milliseconds from now, the current time in
+// milliseconds.
+func VerifyIntegrationRequest(header http.Header, body []byte, secret string, now int64, tolerance int64) *AppError {
+	signature := header.Get(HEADER_INTEGRATION_SIGNATURE)
+
+	timestamp, err := strconv.ParseInt(header.Get(HEADER_INTEGRATION_SIGNATURE_TIMESTAMP), 10, 64)
+	if err != nil {
+		return NewAppError("VerifyIntegrationRequest", "model.integration_signature.verify.timestamp.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	algorithm := ""
+	if i := strings.Index(signature, "="); i > 0 {
+		algorithm = signature[:i]
+	}
+
+	if algorithm == "" || !IsValidIntegrationSigningAlgorithm(algorithm) || !hmac.Equal([]byte(signature), []byte(SignIntegrationRequest(algorithm, secret, timestamp, body))) {
+		return NewAppError("VerifyIntegrationRequest", "model.integration_signature.verify.signature.app_error", nil, "", http.StatusUnauthorized)
+	}
+
+	if timestamp > now+tolerance || now-timestamp > tolerance {
+		return NewAppError("VerifyIntegrationRequest", "model.integration_signature.verify.expired.app_error", nil, "", http.StatusUnauthorized)
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// referenceSignature signs a request the way that the integration docs describe, without using any of the
+// server's code.
+func referenceSignature(algorithm, secret string, timestamp int64, body string) string {
+	newHash := sha256.New
+	if algorithm == "sha512" {
+		newHash = sha512.New
+	}
+
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write([]byte(fmt.Sprintf("%d.%s", timestamp, body)))
+	return algorithm + "=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestSignIntegrationRequest(t *testing.T) {
+	secret := NewRandomString(INTEGRATION_SIGNING_SECRET_LENGTH)
+	timestamp := GetMillis()
+	body := "token=abc&text=hello"
+
+	assert.Equal(t, referenceSignature("sha256", secret, timestamp, body), SignIntegrationRequest(INTEGRATION_SIGNING_ALGORITHM_SHA256, secret, timestamp, []byte(body)))
+	assert.Equal(t, referenceSignature("sha256", secret, timestamp, body), SignIntegrationRequest("", secret, timestamp, []byte(body)), "SHA-256 should be used by default")
+	assert.Equal(t, referenceSignature("sha512", secret, timestamp, body), SignIntegrationRequest(INTEGRATION_SIGNING_ALGORITHM_SHA512, secret, timestamp, []byte(body)))
+
+	header := http.Header{}
+	SetIntegrationSignatureHeaders(header, "", "", timestamp, []byte(body))
+	assert.Empty(t, header, "nothing should be signed without a secret")
+
+	SetIntegrationSignatureHeaders(header, INTEGRATION_SIGNING_ALGORITHM_SHA512, secret, timestamp, []byte(body))
+	assert.Equal(t, fmt.Sprint(timestamp), header.Get(HEADER_INTEGRATION_SIGNATURE_TIMESTAMP))
+	assert.Equal(t, referenceSignature("sha512", secret, timestamp, body), header.Get(HEADER_INTEGRATION_SIGNATURE))
+}
+
+func TestVerifyIntegrationRequest(t *testing.T) {
+	secret := NewRandomString(INTEGRATION_SIGNING_SECRET_LENGTH)
+	now := GetMillis()
+	body := []byte(`{"text": "hello"}`)
+
+	header := http.Header{}
+	SetIntegrationSignatureHeaders(header, INTEGRATION_SIGNING_ALGORITHM_SHA256, secret, now, body)
+	require.Nil(t, VerifyIntegrationRequest(header, body, secret, now, INTEGRATION_SIGNING_DEFAULT_TOLERANCE))
+
+	err := VerifyIntegrationRequest(header, body, NewRandomString(INTEGRATION_SIGNING_SECRET_LENGTH), now, INTEGRATION_SIGNING_DEFAULT_TOLERANCE)
+	require.NotNil(t, err, "should reject requests signed with another secret")
+	assert.Equal(t, "model.integration_signature.verify.signature.app_error", err.Id)
+
+	err = VerifyIntegrationRequest(header, []byte(`{"text": "changed"}`), secret, now, INTEGRATION_SIGNING_DEFAULT_TOLERANCE)
+	require.NotNil(t, err, "should reject requests that were changed after being signed")
+	assert.Equal(t, "model.integration_signature.verify.signature.app_error", err.Id)
+
+	err = VerifyIntegrationRequest(header, body, secret, now+INTEGRATION_SIGNING_DEFAULT_TOLERANCE+1, INTEGRATION_SIGNING_DEFAULT_TOLERANCE)
+	require.NotNil(t, err, "should reject stale requests")
+	assert.Equal(t, "model.integration_signature.verify.expired.app_error", err.Id)
+
+	err = VerifyIntegrationRequest(header, body, secret, now-INTEGRATION_SIGNING_DEFAULT_TOLERANCE-1, INTEGRATION_SIGNING_DEFAULT_TOLERANCE)
+	require.NotNil(t, err, "should reject requests from the future")
+	assert.Equal(t, "model.integration_signature.verify.expired.app_error", err.Id)
+
+	assert.Nil(t, VerifyIntegrationRequest(header, body, secret, now+1000, 2000), "a custom tolerance should be used")
+	assert.NotNil(t, VerifyIntegrationRequest(header, body, secret, now+3000, 2000))
+
+	replayed := http.Header{}
+	replayed.Set(HEADER_INTEGRATION_SIGNATURE, header.Get(HEADER_INTEGRATION_SIGNATURE))
+	replayed.Set(HEADER_INTEGRATION_SIGNATURE_TIMESTAMP, fmt.Sprint(now+60*60*1000))
+	err = VerifyIntegrationRequest(replayed, body, secret, now+60*60*1000, INTEGRATION_SIGNING_DEFAULT_TOLERANCE)
+	require.NotNil(t, err, "the timestamp should be covered by the signature")
+	assert.Equal(t, "model.integration_signature.verify.signature.app_error", err.Id)
+
+	err = VerifyIntegrationRequest(http.Header{}, body, secret, now, INTEGRATION_SIGNING_DEFAULT_TOLERANCE)
+	require.NotNil(t, err)
+	assert.Equal(t, "model.integration_signature.verify.timestamp.app_error", err.Id)
+
+	unknown := http.Header{}
+	unknown.Set(HEADER_INTEGRATION_SIGNATURE_TIMESTAMP, fmt.Sprint(now))
+	unknown.Set(HEADER_INTEGRATION_SIGNATURE, "md5=abc")
+	assert.NotNil(t, VerifyIntegrationRequest(unknown, body, secret, now, INTEGRATION_SIGNING_DEFAULT_TOLERANCE))
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	INVITE_ROLE_MEMBER = "member"
+	INVITE_ROLE_GUEST  = "guest"
+
+	INVITE_MESSAGE_MAX_RUNES = 1024
+)
+
+// Invite is an invitation for one person to join a team with a role, a set of channels to be added
+// to once they've joined and an optional personal message from whoever sent it.
+type Invite struct {
+	Email    string   `json:"email"`
+	Role     string   `json:"role"`
+	Channels []string `json:"channels"`
+	Message  string   `json:"message"`
+}
+
+// InviteResult reports whether the invitation for an email address was queued to be sent. Error is
+// only set if it wasn't, and InvitationId is the id of the invitation's TeamInvitation if it was.
+type InviteResult struct {
+	Email        string    `json:"email"`
+	InvitationId string    `json:"invitation_id,omitempty"`
+	Error        *AppError `json:"error,omitempty"`
+}
+
+func (i *Invite) PreSave() {
+	i.Email = strings.ToLower(strings.TrimSpace(i.Email))
+
+	if i.Role == "" {
+		i.Role = INVITE_ROLE_MEMBER
+	}
+}
+
+func (i *Invite) IsValid() *AppError {
+	if len(i.Email) > USER_EMAIL_MAX_LENGTH || !IsValidEmail(strings.ToLower(i.Email)) {
+		return NewAppError("Invite.IsValid", "model.invite.is_valid.email.app_error", nil, "email="+i.Email, http.StatusBadRequest)
+	}
+
+	if i.Role != INVITE_ROLE_MEMBER && i.Role != INVITE_ROLE_GUEST {
+		return NewAppError("Invite.IsValid", "model.invite.is_valid.role.app_error", nil, "role="+i.Role, http.StatusBadRequest)
+	}
+
+	// guests can only see the channels that they're invited to, so they have to be invited to some
+	if i.Role == INVITE_ROLE_GUEST && len(i.Channels) == 0 {
+		return NewAppError("Invite.IsValid", "model.invite.is_valid.guest_channels.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	for _, channel := range i.Channels {
+		if !IsValidId(channel) {
+			return NewAppError("Invite.IsValid", "model.invite.is_valid.channel.app_error", nil, "channel="+channel, http.StatusBadRequest)
+		}
+	}
+
+	if utf8.RuneCountInString(i.Message) > INVITE_MESSAGE_MAX_RUNES {
+		return NewAppError("Invite.IsValid", "model.invite.is_valid.message.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func InviteListToJson(i []*Invite) string {
+	b, _ := json.Marshal(i)
+	return string(b)
+}
+
+func InviteListFromJson(data io.Reader) []*Invite {
+	var i []*Invite
+	json.NewDecoder(data).Decode(&i)
+	return i
+}
+
+func InviteResultListToJson(r []*InviteResult) string {
+	b, _ := json.Marshal(r)
+	return string(b)
+}
+
+func InviteResultListFromJson(data io.Reader) []*InviteResult {
+	var r []*InviteResult
+	json.NewDecoder(data).Decode(&r)
+	return r
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// InviteLink is a link for joining a team that can be limited to a number of uses, expire and be
+// revoked. Unlike the team's InviteId, every use of it is recorded. A MaxUses or ExpiresAt of 0 means
+// that the link has no limit.
+type InviteLink struct {
+	Id        string `json:"id"`
+	TeamId    string `json:"team_id"`
+	CreatorId string `json:"creator_id"`
+	CreateAt  int64  `json:"create_at"`
+	ExpiresAt int64  `json:"expires_at"`
+	DeleteAt  int64  `json:"delete_at"`
+	MaxUses   int    `json:"max_uses"`
+	Uses      int    `json:"uses"`
+	// GuestOnly links create guest accounts and can't be used by members.
+	GuestOnly bool `json:"guest_only"`
+}
+
+// InviteLinkRedemption records that a user joined a team using an invite link.
+type InviteLinkRedemption struct {
+	LinkId   string `json:"link_id"`
+	UserId   string `json:"user_id"`
+	CreateAt int64  `json:"create_at"`
+}
+
+func (l *InviteLink) IsValid() *AppError {
+	if len(l.Id) != 26 {
+		return NewAppError("InviteLink.IsValid", "model.invite_link.is_valid.id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if len(l.TeamId) != 26 {
+		return NewAppError("InviteLink.IsValid", "model.invite_link.is_valid.team_id.app_error", nil, "id="+l.Id, http.StatusBadRequest)
+	}
+
+	if len(l.CreatorId) != 26 {
+		return NewAppError("InviteLink.IsValid", "model.invite_link.is_valid.creator_id.app_error", nil, "id="+l.Id, http.StatusBadRequest)
+	}
+
+	if l.CreateAt == 0 {
+		return NewAppError("InviteLink.IsValid", "model.invite_link.is_valid.create_at.app_error", nil, "id="+l.Id, http.StatusBadRequest)
+	}
+
+	if l.ExpiresAt < 0 || (l.ExpiresAt != 0 && l.ExpiresAt <= l.CreateAt) {
+		return NewAppError("InviteLink.IsValid", "model.invite_link.is_valid.expires_at.app_error", nil, "id="+l.Id, http.StatusBadRequest)
+	}
+
+	if l.MaxUses < 0 {
+		return NewAppError("InviteLink.IsValid", "model.invite_link.is_valid.max_uses.app_error", nil, "id="+l.Id, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (l *InviteLink) PreSave() {
+	l.Id = NewId()
+	l.CreateAt = GetMillis()
+	l.DeleteAt = 0
+	l.Uses = 0
+}
+
+// IsUsable returns true if the link hasn't been revoked, expired or used up at the given time, in
+// milliseconds.
+func (l *InviteLink) IsUsable(now int64) bool {
+	return l.DeleteAt == 0 &&
+		(l.ExpiresAt == 0 || l.ExpiresAt > now) &&
+		(l.MaxUses == 0 || l.Uses < l.MaxUses)
+}
+
+func (l *InviteLink) ToJson() string {
+	b, _ := json.Marshal(l)
+	return string(b)
+}
+
+func InviteLinkFromJson(data io.Reader) *InviteLink {
+	var l *InviteLink
+	json.NewDecoder(data).Decode(&l)
+	return l
+}
+
+func InviteLinkListToJson(l []*InviteLink) string {
+	b, _ := json.Marshal(l)
+	return string(b)
+}
+
+func InviteLinkListFromJson(data io.Reader) []*InviteLink {
+	var l []*InviteLink
+	json.NewDecoder(data).Decode(&l)
+	return l
+}
+
+func InviteLinkRedemptionListToJson(r []*InviteLinkRedemption) string {
+	b, _ := json.Marshal(r)
+	return string(b)
+}
+
+func InviteLinkRedemptionListFromJson(data io.Reader) []*InviteLinkRedemption {
+	var r []*InviteLinkRedemption
+	json.NewDecoder(data).Decode(&r)
+	return r
+}
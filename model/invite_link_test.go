@@ -0,0 +1,56 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInviteLinkIsValid(t *testing.T) {
+	link := &InviteLink{TeamId: NewId(), CreatorId: NewId(), MaxUses: 5}
+	link.PreSave()
+	require.Nil(t, link.IsValid())
+
+	for id, change := range map[string]func(*InviteLink){
+		"model.invite_link.is_valid.id.app_error":         func(l *InviteLink) { l.Id = "" },
+		"model.invite_link.is_valid.team_id.app_error":    func(l *InviteLink) { l.TeamId = "" },
+		"model.invite_link.is_valid.creator_id.app_error": func(l *InviteLink) { l.CreatorId = "" },
+		"model.invite_link.is_valid.create_at.app_error":  func(l *InviteLink) { l.CreateAt = 0 },
+		"model.invite_link.is_valid.expires_at.app_error": func(l *InviteLink) { l.ExpiresAt = l.CreateAt - 1 },
+		"model.invite_link.is_valid.max_uses.app_error":   func(l *InviteLink) { l.MaxUses = -1 },
+	} {
+		invalid := *link
+		change(&invalid)
+
+		err := invalid.IsValid()
+		if assert.NotNil(t, err, id) {
+			assert.Equal(t, id, err.Id)
+		}
+	}
+}
+
+func TestInviteLinkIsUsable(t *testing.T) {
+	now := GetMillis()
+
+	assert.True(t, (&InviteLink{}).IsUsable(now))
+	assert.True(t, (&InviteLink{MaxUses: 2, Uses: 1, ExpiresAt: now + 1}).IsUsable(now))
+	assert.False(t, (&InviteLink{MaxUses: 2, Uses: 2}).IsUsable(now))
+	assert.False(t, (&InviteLink{ExpiresAt: now}).IsUsable(now))
+	assert.False(t, (&InviteLink{DeleteAt: now}).IsUsable(now))
+}
+
+func TestInviteLinkJson(t *testing.T) {
+	link := &InviteLink{TeamId: NewId(), CreatorId: NewId(), GuestOnly: true}
+	link.PreSave()
+
+	assert.Equal(t, link, InviteLinkFromJson(strings.NewReader(link.ToJson())))
+	assert.Equal(t, []*InviteLink{link}, InviteLinkListFromJson(strings.NewReader(InviteLinkListToJson([]*InviteLink{link}))))
+
+	redemptions := []*InviteLinkRedemption{{LinkId: link.Id, UserId: NewId(), CreateAt: 1}}
+	assert.Equal(t, redemptions, InviteLinkRedemptionListFromJson(strings.NewReader(InviteLinkRedemptionListToJson(redemptions))))
+}
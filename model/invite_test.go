@@ -0,0 +1,52 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInviteIsValid(t *testing.T) {
+	invite := &Invite{Email: " Success@Example.com "}
+	invite.PreSave()
+	assert.Equal(t, "success@example.com", invite.Email)
+	assert.Equal(t, INVITE_ROLE_MEMBER, invite.Role)
+	require.Nil(t, invite.IsValid())
+
+	for id, invite := range map[string]*Invite{
+		"model.invite.is_valid.email.app_error":          {Email: "example.com", Role: INVITE_ROLE_MEMBER},
+		"model.invite.is_valid.role.app_error":           {Email: "success@example.com", Role: "admin"},
+		"model.invite.is_valid.guest_channels.app_error": {Email: "success@example.com", Role: INVITE_ROLE_GUEST},
+		"model.invite.is_valid.channel.app_error":        {Email: "success@example.com", Role: INVITE_ROLE_MEMBER, Channels: []string{"town-square"}},
+		"model.invite.is_valid.message.app_error":        {Email: "success@example.com", Role: INVITE_ROLE_MEMBER, Message: strings.Repeat("a", INVITE_MESSAGE_MAX_RUNES+1)},
+	} {
+		err := invite.IsValid()
+		if assert.NotNil(t, err, id) {
+			assert.Equal(t, id, err.Id)
+		}
+	}
+
+	invite = &Invite{Email: "success@example.com", Role: INVITE_ROLE_GUEST, Channels: []string{NewId()}}
+	assert.Nil(t, invite.IsValid())
+}
+
+func TestInviteJson(t *testing.T) {
+	invites := []*Invite{{Email: "success@example.com", Role: INVITE_ROLE_GUEST, Channels: []string{NewId()}, Message: "Welcome"}}
+	assert.Equal(t, invites, InviteListFromJson(strings.NewReader(InviteListToJson(invites))))
+
+	results := []*InviteResult{
+		{Email: "success@example.com"},
+		{Email: "duplicate@example.com", Error: NewAppError("where", "id", nil, "", 400)},
+	}
+	decoded := InviteResultListFromJson(strings.NewReader(InviteResultListToJson(results)))
+	require.Len(t, decoded, 2)
+	assert.Nil(t, decoded[0].Error)
+	if assert.NotNil(t, decoded[1].Error) {
+		assert.Equal(t, "id", decoded[1].Error.Id)
+	}
+}
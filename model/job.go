@@ -16,6 +16,17 @@ const (
 	JOB_TYPE_ELASTICSEARCH_POST_INDEXING    = "elasticsearch_post_indexing"
 	JOB_TYPE_ELASTICSEARCH_POST_AGGREGATION = "elasticsearch_post_aggregation"
 	JOB_TYPE_LDAP_SYNC                      = "ldap_sync"
+	JOB_TYPE_BASIC_RETENTION                = "basic_retention"
+	JOB_TYPE_USER_DEACTIVATION              = "user_deactivation"
+	JOB_TYPE_PREFERENCE_CLEANUP             = "preference_cleanup"
+	JOB_TYPE_ANALYTICS_ROLLUP               = "analytics_rollup"
+	JOB_TYPE_IDLE_USER_DEACTIVATION         = "idle_user_deactivation"
+	JOB_TYPE_CHANNEL_MEMBER_COUNTS          = "channel_member_counts"
+	JOB_TYPE_CHANNEL_ARCHIVING              = "channel_archiving"
+	JOB_TYPE_FILE_STORAGE_USAGE             = "file_storage_usage"
+	JOB_TYPE_CHANNEL_DELETION               = "channel_deletion"
+	JOB_TYPE_FILE_STORE_VERIFICATION        = "file_store_verification"
+	JOB_TYPE_TEAM_DELETION                  = "team_deletion"
 
 	JOB_STATUS_PENDING          = "pending"
 	JOB_STATUS_IN_PROGRESS      = "in_progress"
@@ -52,6 +63,17 @@ func (j *Job) IsValid() *AppError {
 	case JOB_TYPE_ELASTICSEARCH_POST_AGGREGATION:
 	case JOB_TYPE_LDAP_SYNC:
 	case JOB_TYPE_MESSAGE_EXPORT:
+	case JOB_TYPE_BASIC_RETENTION:
+	case JOB_TYPE_USER_DEACTIVATION:
+	case JOB_TYPE_PREFERENCE_CLEANUP:
+	case JOB_TYPE_ANALYTICS_ROLLUP:
+	case JOB_TYPE_IDLE_USER_DEACTIVATION:
+	case JOB_TYPE_CHANNEL_MEMBER_COUNTS:
+	case JOB_TYPE_CHANNEL_ARCHIVING:
+	case JOB_TYPE_FILE_STORAGE_USAGE:
+	case JOB_TYPE_CHANNEL_DELETION:
+	case JOB_TYPE_FILE_STORE_VERIFICATION:
+	case JOB_TYPE_TEAM_DELETION:
 	default:
 		return NewAppError("Job.IsValid", "model.job.is_valid.type.app_error", nil, "id="+j.Id, http.StatusBadRequest)
 	}
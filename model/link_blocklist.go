@@ -0,0 +1,320 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/mattermost/mattermost-server/utils/markdown"
+)
+
+const (
+	BLOCKED_DOMAIN_MAX_LENGTH = 255
+	BLOCKED_DOMAINS_MAX_COUNT = 10000
+
+	// BLOCKED_DOMAIN_WILDCARD_PREFIX makes a blocked domain match its subdomains instead of itself.
+	BLOCKED_DOMAIN_WILDCARD_PREFIX = "*."
+)
+
+// BlockedDomain is a domain that posts can't link to. Domains that start with *. block every subdomain of the rest
+// of the domain, but not the domain itself.
+type BlockedDomain struct {
+	Domain    string `json:"domain"`
+	CreatorId string `json:"creator_id"`
+	CreateAt  int64  `json:"create_at"`
+}
+
+func (o *BlockedDomain) PreSave() {
+	if o.CreateAt == 0 {
+		o.CreateAt = GetMillis()
+	}
+}
+
+func (o *BlockedDomain) IsValid() *AppError {
+	if normalized, ok := NormalizeBlockedDomain(o.Domain); !ok || normalized != o.Domain {
+		return NewAppError("BlockedDomain.IsValid", "model.blocked_domain.is_valid.domain.app_error", map[string]interface{}{"Domain": o.Domain}, "", http.StatusBadRequest)
+	}
+
+	if !IsValidId(o.CreatorId) {
+		return NewAppError("BlockedDomain.IsValid", "model.blocked_domain.is_valid.creator_id.app_error", nil, "domain="+o.Domain, http.StatusBadRequest)
+	}
+
+	if o.CreateAt == 0 {
+		return NewAppError("BlockedDomain.IsValid", "model.blocked_domain.is_valid.create_at.app_error", nil, "domain="+o.Domain, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func BlockedDomainListToJson(domains []*BlockedDomain) string {
+	b, _ := json.Marshal(domains)
+	return string(b)
+}
+
+func BlockedDomainListFromJson(data io.Reader) []*BlockedDomain {
+	var domains []*BlockedDomain
+	json.NewDecoder(data).Decode(&domains)
+	return domains
+}
+
+// NormalizeLinkHost returns the form of a host name that's compared against blocked domains, which is lowercase
+// ASCII with internationalized labels in punycode and no trailing dot. Full width characters and other compatibility
+// forms are replaced by the ones that they're looked up as.
+func NormalizeLinkHost(host string) (string, bool) {
+	host = strings.TrimSpace(host)
+	if host == "" {
+		return "", false
+	}
+
+	normalized, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		// Fall back to a plain comparison for names that are used in practice but aren't strictly valid, such as
+		// ones with underscores in them
+		if !isASCII(host) {
+			return "", false
+		}
+		normalized = strings.ToLower(host)
+	}
+
+	normalized = strings.TrimSuffix(normalized, ".")
+	if normalized == "" || len(normalized) > BLOCKED_DOMAIN_MAX_LENGTH || strings.ContainsAny(normalized, " /\\:@?#%") {
+		return "", false
+	}
+
+	return normalized, true
+}
+
+// NormalizeBlockedDomain returns the form of a domain that's saved in the blocklist, along with whether it can be
+// blocked. A leading *. is kept to block subdomains.
+func NormalizeBlockedDomain(domain string) (string, bool) {
+	domain = strings.TrimSpace(domain)
+
+	prefix := ""
+	if strings.HasPrefix(domain, BLOCKED_DOMAIN_WILDCARD_PREFIX) {
+		prefix = BLOCKED_DOMAIN_WILDCARD_PREFIX
+		domain = domain[len(BLOCKED_DOMAIN_WILDCARD_PREFIX):]
+	}
+
+	normalized, ok := NormalizeLinkHost(domain)
+	if !ok || strings.Contains(normalized, "*") || len(prefix+normalized) > BLOCKED_DOMAIN_MAX_LENGTH {
+		return "", false
+	}
+
+	return prefix + normalized, true
+}
+
+// homoglyphs maps letters from other scripts that look the same as basic Latin ones to those Latin letters.
+var homoglyphs = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'с': 'c', 'ԁ': 'd', 'е': 'e', 'ё': 'e', 'һ': 'h', 'і': 'i', 'ї': 'i', 'ј': 'j', 'ӏ': 'l', 'п': 'n',
+	'о': 'o', 'р': 'p', 'ԛ': 'q', 'г': 'r', 'ѕ': 's', 'ԝ': 'w', 'х': 'x', 'у': 'y', 'ь': 'b',
+	// Greek
+	'α': 'a', 'ϲ': 'c', 'ι': 'i', 'ϳ': 'j', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p', 'υ': 'u', 'χ': 'x',
+	// Latin letters that look like other ones
+	'ɑ': 'a', 'ɡ': 'g', 'ı': 'i', 'ɩ': 'i', 'ȷ': 'j', 'ſ': 's',
+}
+
+// linkHostSkeleton returns what a normalized host looks like, so that hosts that can't be told apart by reading them
+// compare the same. Letters are stripped of accents and replaced by the basic Latin letters that they look like.
+func linkHostSkeleton(host string) string {
+	if !strings.Contains(host, "xn--") {
+		return host
+	}
+
+	display, err := idna.ToUnicode(host)
+	if err != nil {
+		return host
+	}
+
+	var skeleton strings.Builder
+	for _, r := range norm.NFD.String(display) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+
+		if latin, ok := homoglyphs[r]; ok {
+			r = latin
+		}
+		skeleton.WriteRune(r)
+	}
+
+	return skeleton.String()
+}
+
+// LinkBlocklist matches the hosts of links against a list of blocked domains, including hosts written with letters
+// from other scripts that look like the blocked ones.
+type LinkBlocklist struct {
+	domains    map[string]string
+	subdomains map[string]string
+}
+
+// NewLinkBlocklist creates a blocklist for the given domains, which are expected to have been normalized with
+// NormalizeBlockedDomain.
+func NewLinkBlocklist(domains []string) *LinkBlocklist {
+	blocklist := &LinkBlocklist{
+		domains:    make(map[string]string),
+		subdomains: make(map[string]string),
+	}
+
+	for _, domain := range domains {
+		if strings.HasPrefix(domain, BLOCKED_DOMAIN_WILDCARD_PREFIX) {
+			blocklist.subdomains[linkHostSkeleton(domain[len(BLOCKED_DOMAIN_WILDCARD_PREFIX):])] = domain
+		} else {
+			blocklist.domains[linkHostSkeleton(domain)] = domain
+		}
+	}
+
+	return blocklist
+}
+
+// IsEmpty returns true if the blocklist doesn't block anything.
+func (b *LinkBlocklist) IsEmpty() bool {
+	return len(b.domains) == 0 && len(b.subdomains) == 0
+}
+
+// Match returns the blocked domain that matches a host, or an empty string if the host isn't blocked.
+func (b *LinkBlocklist) Match(host string) string {
+	normalized, ok := NormalizeLinkHost(host)
+	if !ok {
+		return ""
+	}
+
+	skeleton := linkHostSkeleton(normalized)
+	if domain, ok := b.domains[skeleton]; ok {
+		return domain
+	}
+
+	for i := strings.IndexByte(skeleton, '.'); i != -1; i = strings.IndexByte(skeleton, '.') {
+		skeleton = skeleton[i+1:]
+		if domain, ok := b.subdomains[skeleton]; ok {
+			return domain
+		}
+	}
+
+	return ""
+}
+
+// MessageLink is a link in the message of a post, either written with markdown or autolinked from a URL in the
+// text.
+type MessageLink struct {
+	URL  string
+	Host string
+
+	// rng is where the link is in the message. label is where the text shown for it is, or nil if it's shown as
+	// it's written.
+	rng   markdown.Range
+	label *markdown.Range
+}
+
+// FindMessageLinks returns every link and image in a message that goes to another host, including links nested in
+// the text of other links and those that are only autolinked.
+func FindMessageLinks(message string) []*MessageLink {
+	var links []*MessageLink
+
+	add := func(destination string, rng markdown.Range, label *markdown.Range) {
+		if host := linkDestinationHost(destination); host != "" {
+			links = append(links, &MessageLink{URL: destination, Host: host, rng: rng, label: label})
+		}
+	}
+
+	markdown.Inspect(message, func(blockOrInline interface{}) bool {
+		switch v := blockOrInline.(type) {
+		case *markdown.InlineLink:
+			add(v.Destination(), v.Range, &v.RawLabel)
+		case *markdown.InlineImage:
+			add(v.Destination(), v.Range, &v.RawLabel)
+		case *markdown.ReferenceLink:
+			add(v.ReferenceDefinition.Destination(), v.Range, &v.RawLabel)
+		case *markdown.ReferenceImage:
+			add(v.ReferenceDefinition.Destination(), v.Range, &v.RawLabel)
+		case *markdown.Autolink:
+			add(v.Destination(), v.RawDestination, nil)
+		}
+		return true
+	})
+
+	return links
+}
+
+// linkDestinationHost returns the host that a link goes to, or an empty string if it's relative to the page that
+// it's on. Links to web pages are read the same way as browsers read them, where backslashes are treated as slashes
+// and any number of them can come after the scheme.
+func linkDestinationHost(destination string) string {
+	destination = strings.Replace(strings.TrimSpace(destination), `\`, "/", -1)
+
+	parsed, err := url.Parse(destination)
+	if err != nil {
+		return ""
+	}
+
+	if parsed.Host == "" {
+		switch strings.ToLower(parsed.Scheme) {
+		case "http", "https", "ftp":
+			rest := strings.TrimLeft(destination[len(parsed.Scheme)+1:], "/")
+			if parsed, err = url.Parse(parsed.Scheme + "://" + rest); err != nil {
+				return ""
+			}
+		}
+	}
+
+	return parsed.Hostname()
+}
+
+// StripMessageLinks returns a message with the given links found by FindMessageLinks turned into plain text. Links
+// written with markdown are replaced by their text, and autolinked URLs are put in code spans so that they're
+// still shown but can't be clicked.
+func StripMessageLinks(message string, links []*MessageLink) string {
+	sorted := make([]*MessageLink, len(links))
+	copy(sorted, links)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].rng.Position != sorted[j].rng.Position {
+			return sorted[i].rng.Position < sorted[j].rng.Position
+		}
+		return sorted[i].rng.End > sorted[j].rng.End
+	})
+
+	return stripMessageLinks(message, markdown.Range{Position: 0, End: len(message)}, sorted)
+}
+
+func stripMessageLinks(message string, within markdown.Range, links []*MessageLink) string {
+	var stripped strings.Builder
+
+	position := within.Position
+	for i, link := range links {
+		if link.rng.Position < position || link.rng.End > within.End {
+			continue
+		}
+
+		stripped.WriteString(message[position:link.rng.Position])
+		if link.label != nil {
+			// Any other links in the text are stripped too so that they're not left behind as autolinks
+			stripped.WriteString(stripMessageLinks(message, *link.label, links[i+1:]))
+		} else {
+			stripped.WriteString("`" + message[link.rng.Position:link.rng.End] + "`")
+		}
+		position = link.rng.End
+	}
+	stripped.WriteString(message[position:within.End])
+
+	return stripped.String()
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeBlockedDomain(t *testing.T) {
+	for input, expected := range map[string]string{
+		"Example.COM":      "example.com",
+		" example.com. ":   "example.com",
+		"*.Example.com":    "*.example.com",
+		"例子.测试":            "xn--fsqu00a.xn--0zwm56d",
+		"*.bücher.example": "*.xn--bcher-kva.example",
+		"ｅｘａｍｐｌｅ.com":      "example.com",
+		"under_score.com":  "under_score.com",
+	} {
+		actual, ok := NormalizeBlockedDomain(input)
+		assert.True(t, ok, input)
+		assert.Equal(t, expected, actual, input)
+	}
+
+	for _, input := range []string{"", "*.", "example.com/path", "user@example.com", "example.com:8080", "*.*.example.com", "ex*mple.com"} {
+		_, ok := NormalizeBlockedDomain(input)
+		assert.False(t, ok, input)
+	}
+}
+
+func TestLinkBlocklist(t *testing.T) {
+	blocklist := NewLinkBlocklist([]string{"example.com", "*.evil.org", "xn--bcher-kva.example"})
+
+	for host, expected := range map[string]string{
+		"example.com":           "example.com",
+		"EXAMPLE.com.":          "example.com",
+		"www.example.com":       "",
+		"notexample.com":        "",
+		"evil.org":              "",
+		"a.evil.org":            "*.evil.org",
+		"a.b.Evil.org":          "*.evil.org",
+		"bücher.example":        "xn--bcher-kva.example",
+		"xn--bcher-kva.example": "xn--bcher-kva.example",
+		"BÜCHER.example":        "xn--bcher-kva.example",
+		"ｅｘａｍｐｌｅ.com":           "example.com",
+		"exаmple.com":           "example.com",
+		"еxample.com":           "example.com",
+		"examplе.com":           "example.com",
+		"éxample.com":           "example.com",
+		"a.еvil.org":            "*.evil.org",
+		"exampel.com":           "",
+		"":                      "",
+	} {
+		assert.Equal(t, expected, blocklist.Match(host), host)
+	}
+
+	assert.False(t, blocklist.IsEmpty())
+	assert.True(t, NewLinkBlocklist(nil).IsEmpty())
+}
+
+func TestFindMessageLinks(t *testing.T) {
+	hosts := func(message string) []string {
+		found := []string{}
+		for _, link := range FindMessageLinks(message) {
+			found = append(found, link.Host)
+		}
+		return found
+	}
+
+	assert.Equal(t, []string{}, hosts("no links here, just example.com"))
+	assert.Equal(t, []string{"example.com", "www.example.org"}, hosts("see https://example.com/path and www.example.org"))
+	assert.Equal(t, []string{"example.com", "example.org"}, hosts("[see http://example.org](https://example.com)"))
+	assert.Equal(t, []string{"example.com", "example.org"}, hosts("![image](https://example.com/a.png) and [link][ref]\n\n[ref]: http://example.org"))
+	assert.Equal(t, []string{"例子.测试"}, hosts("https://例子.测试/path"))
+	assert.Equal(t, []string{"example.com", "example.org"}, hosts(`[click](https:\\example.com) [here](https:example.org)`))
+	assert.Equal(t, []string{}, hosts("[relative](/path) and `http://example.com`"))
+}
+
+func TestStripMessageLinks(t *testing.T) {
+	blocklist := NewLinkBlocklist([]string{"evil.com", "*.evil.com"})
+
+	strip := func(message string) string {
+		var blocked []*MessageLink
+		for _, link := range FindMessageLinks(message) {
+			if blocklist.Match(link.Host) != "" {
+				blocked = append(blocked, link)
+			}
+		}
+		return StripMessageLinks(message, blocked)
+	}
+
+	for name, tc := range map[string]struct {
+		Message  string
+		Expected string
+	}{
+		"no blocked links": {
+			Message:  "see [this](https://example.com) and https://example.org",
+			Expected: "see [this](https://example.com) and https://example.org",
+		},
+		"markdown link": {
+			Message:  "see [this *page*](https://evil.com/login) now",
+			Expected: "see this *page* now",
+		},
+		"image": {
+			Message:  "![logo](http://evil.com/logo.png)",
+			Expected: "logo",
+		},
+		"reference link": {
+			Message:  "[login][1]\n\n[1]: https://evil.com",
+			Expected: "login\n\n[1]: https://evil.com",
+		},
+		"autolink": {
+			Message:  "go to https://evil.com/login, or www.evil.com.",
+			Expected: "go to `https://evil.com/login`, or `www.evil.com`.",
+		},
+		"blocked link in an allowed link": {
+			Message:  "[see https://evil.com](https://example.com)",
+			Expected: "[see `https://evil.com`](https://example.com)",
+		},
+		"allowed link in a blocked link": {
+			Message:  "[see https://example.com](https://evil.com)",
+			Expected: "see https://example.com",
+		},
+		"blocked link in a blocked link": {
+			Message:  "[see https://evil.com](https://evil.com)",
+			Expected: "see `https://evil.com`",
+		},
+		"image in a link": {
+			Message:  "[![logo](https://evil.com/logo.png)](https://evil.com)",
+			Expected: "logo",
+		},
+		"homoglyph": {
+			Message:  "[sign in](https://еvil.com)",
+			Expected: "sign in",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.Expected, strip(tc.Message))
+		})
+	}
+}
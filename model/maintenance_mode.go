@@ -0,0 +1,44 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"unicode/utf8"
+)
+
+const (
+	MAINTENANCE_MODE_MESSAGE_MAX_RUNES = 256
+)
+
+// MaintenanceMode is used to drain traffic from every server in the cluster before it's upgraded. While it's
+// enabled, only system admins can use the API, unless it's read-only, in which case everyone can still read but
+// nothing can be changed, such as while the database is being migrated.
+type MaintenanceMode struct {
+	Enabled   bool   `json:"enabled"`
+	ReadOnly  bool   `json:"read_only"`
+	Message   string `json:"message"`
+	StartedAt int64  `json:"started_at"`
+}
+
+func (o *MaintenanceMode) IsValid() *AppError {
+	if utf8.RuneCountInString(o.Message) > MAINTENANCE_MODE_MESSAGE_MAX_RUNES {
+		return NewAppError("MaintenanceMode.IsValid", "model.maintenance_mode.is_valid.message.app_error", map[string]interface{}{"MaxLength": MAINTENANCE_MODE_MESSAGE_MAX_RUNES}, "", http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (o *MaintenanceMode) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func MaintenanceModeFromJson(data io.Reader) *MaintenanceMode {
+	var o *MaintenanceMode
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
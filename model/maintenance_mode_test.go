@@ -0,0 +1,29 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaintenanceModeIsValid(t *testing.T) {
+	mode := &MaintenanceMode{Enabled: true}
+	assert.Nil(t, mode.IsValid())
+
+	mode.Message = strings.Repeat("a", MAINTENANCE_MODE_MESSAGE_MAX_RUNES)
+	assert.Nil(t, mode.IsValid())
+
+	mode.Message = strings.Repeat("a", MAINTENANCE_MODE_MESSAGE_MAX_RUNES+1)
+	assert.NotNil(t, mode.IsValid())
+}
+
+func TestMaintenanceModeJson(t *testing.T) {
+	mode := &MaintenanceMode{Enabled: true, ReadOnly: true, Message: "Upgrading", StartedAt: 1000}
+
+	assert.Equal(t, mode, MaintenanceModeFromJson(strings.NewReader(mode.ToJson())))
+	assert.Nil(t, MaintenanceModeFromJson(strings.NewReader("junk")))
+}
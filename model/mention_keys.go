@@ -0,0 +1,112 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MENTION_KEY_CASE_SENSITIVE_PREFIX marks a mention key that only matches when the case is the same.
+const MENTION_KEY_CASE_SENSITIVE_PREFIX = "="
+
+// MentionKey is one of the words or phrases that a user is mentioned by.
+type MentionKey struct {
+	Key           string
+	CaseSensitive bool
+}
+
+// ParseMentionKeys parses the mention_keys notify prop, a comma separated list of keys. A key may contain
+// spaces to be matched as a phrase, may be put in double quotes to contain commas, with a double quote
+// written as two, and is matched case sensitively if it starts with MENTION_KEY_CASE_SENSITIVE_PREFIX.
+// Blank keys are skipped, so a plain list like "bob,@bob" parses to the same keys as it always has.
+func ParseMentionKeys(value string) []MentionKey {
+	keys := []MentionKey{}
+
+	runes := []rune(value)
+	for start := 0; start <= len(runes); {
+		key, next := parseMentionKey(runes, start)
+		if len(key.Key) > 0 {
+			keys = append(keys, key)
+		}
+		start = next
+	}
+
+	return keys
+}
+
+// parseMentionKey parses the key starting at runes[start] and returns it together with the start of the
+// next key.
+func parseMentionKey(runes []rune, start int) (MentionKey, int) {
+	key := MentionKey{}
+
+	i := start
+	for i < len(runes) && unicode.IsSpace(runes[i]) {
+		i++
+	}
+
+	if i < len(runes) && string(runes[i]) == MENTION_KEY_CASE_SENSITIVE_PREFIX {
+		key.CaseSensitive = true
+		i++
+	}
+
+	if i < len(runes) && runes[i] == '"' {
+		text := []rune{}
+		for i++; i < len(runes); i++ {
+			if runes[i] == '"' {
+				if i+1 < len(runes) && runes[i+1] == '"' {
+					i++
+				} else {
+					break
+				}
+			}
+			text = append(text, runes[i])
+		}
+		key.Key = string(text)
+
+		// anything between the closing quote and the next comma is ignored
+		for i < len(runes) && runes[i] != ',' {
+			i++
+		}
+
+		return key, i + 1
+	}
+
+	end := i
+	for end < len(runes) && runes[end] != ',' {
+		end++
+	}
+	key.Key = strings.TrimSpace(string(runes[i:end]))
+
+	return key, end + 1
+}
+
+func (k MentionKey) String() string {
+	prefix := ""
+	if k.CaseSensitive {
+		prefix = MENTION_KEY_CASE_SENSITIVE_PREFIX
+	}
+
+	if strings.ContainsAny(k.Key, ",\"") || strings.IndexFunc(k.Key, unicode.IsSpace) != -1 || strings.HasPrefix(k.Key, MENTION_KEY_CASE_SENSITIVE_PREFIX) {
+		return prefix + "\"" + strings.Replace(k.Key, "\"", "\"\"", -1) + "\""
+	}
+
+	return prefix + k.Key
+}
+
+// IsPhrase returns true if the key is made of more than one word.
+func (k MentionKey) IsPhrase() bool {
+	return len(strings.Fields(k.Key)) > 1
+}
+
+// MentionKeysToString formats the keys to be stored in the mention_keys notify prop, so that
+// ParseMentionKeys returns them again.
+func MentionKeysToString(keys []MentionKey) string {
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		values[i] = key.String()
+	}
+
+	return strings.Join(values, ",")
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMentionKeys(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Value    string
+		Expected []MentionKey
+	}{
+		"empty": {
+			Value:    "",
+			Expected: []MentionKey{},
+		},
+		"comma separated list": {
+			Value:    "user,@user,deploy",
+			Expected: []MentionKey{{Key: "user"}, {Key: "@user"}, {Key: "deploy"}},
+		},
+		"blank keys": {
+			Value:    ",user,, ,@user,",
+			Expected: []MentionKey{{Key: "user"}, {Key: "@user"}},
+		},
+		"unquoted phrase": {
+			Value:    "user, deploy failed ",
+			Expected: []MentionKey{{Key: "user"}, {Key: "deploy failed"}},
+		},
+		"quoted phrase": {
+			Value:    `user,"deploy failed"`,
+			Expected: []MentionKey{{Key: "user"}, {Key: "deploy failed"}},
+		},
+		"quoted phrase with a comma and a quote": {
+			Value:    `"well, ""that"" failed",user`,
+			Expected: []MentionKey{{Key: `well, "that" failed`}, {Key: "user"}},
+		},
+		"unterminated quote": {
+			Value:    `user,"deploy failed`,
+			Expected: []MentionKey{{Key: "user"}, {Key: "deploy failed"}},
+		},
+		"case sensitive": {
+			Value:    `=Bob,="Deploy Failed",bob`,
+			Expected: []MentionKey{{Key: "Bob", CaseSensitive: true}, {Key: "Deploy Failed", CaseSensitive: true}, {Key: "bob"}},
+		},
+		"only case sensitive prefix": {
+			Value:    "=,user",
+			Expected: []MentionKey{{Key: "user"}},
+		},
+		"prefix inside a key": {
+			Value:    "a=b",
+			Expected: []MentionKey{{Key: "a=b"}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.Expected, ParseMentionKeys(tc.Value))
+		})
+	}
+}
+
+func TestMentionKeysToString(t *testing.T) {
+	assert.Equal(t, "", MentionKeysToString(nil))
+	assert.Equal(t, "user,@user", MentionKeysToString([]MentionKey{{Key: "user"}, {Key: "@user"}}))
+	assert.Equal(t, `"deploy failed",=Bob,="Deploy Failed"`, MentionKeysToString([]MentionKey{
+		{Key: "deploy failed"},
+		{Key: "Bob", CaseSensitive: true},
+		{Key: "Deploy Failed", CaseSensitive: true},
+	}))
+
+	for _, keys := range [][]MentionKey{
+		{{Key: `well, "that" failed`}, {Key: "user"}},
+		{{Key: "=user"}, {Key: "=user", CaseSensitive: true}},
+		{{Key: `"quoted"`}},
+	} {
+		assert.Equal(t, keys, ParseMentionKeys(MentionKeysToString(keys)))
+	}
+}
+
+func TestMentionKeyIsPhrase(t *testing.T) {
+	assert.False(t, MentionKey{Key: "deploy"}.IsPhrase())
+	assert.True(t, MentionKey{Key: "deploy failed"}.IsPhrase())
+}
+
+func TestUserPreUpdateMentionKeys(t *testing.T) {
+	user := User{Username: "user", NotifyProps: StringMap{"mention_keys": `User,,@User,"Deploy Failed",=Bob,="Deploy Failed"`}}
+	user.PreUpdate()
+	assert.Equal(t, `user,@user,"deploy failed",=Bob,="Deploy Failed"`, user.NotifyProps["mention_keys"])
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+const (
+	NOTIFICATION_TRACE_MAX_TTL_SECONDS = 24 * 60 * 60
+
+	NOTIFICATION_TRACE_TYPE_MENTION = "mention"
+	NOTIFICATION_TRACE_TYPE_EMAIL   = "email"
+	NOTIFICATION_TRACE_TYPE_PUSH    = "push"
+
+	NOTIFICATION_TRACE_CHECK_MENTIONED       = "mentioned"
+	NOTIFICATION_TRACE_CHECK_ALL_ACTIVITY    = "all_activity"
+	NOTIFICATION_TRACE_CHECK_ENABLED         = "enabled"
+	NOTIFICATION_TRACE_CHECK_PREFERENCE      = "preference"
+	NOTIFICATION_TRACE_CHECK_CHANNEL_MUTED   = "channel_muted"
+	NOTIFICATION_TRACE_CHECK_EMAIL_VERIFIED  = "email_verified"
+	NOTIFICATION_TRACE_CHECK_DEACTIVATED     = "deactivated"
+	NOTIFICATION_TRACE_CHECK_STATUS          = "status"
+	NOTIFICATION_TRACE_CHECK_EMAIL_BATCHING  = "email_batching"
+	NOTIFICATION_TRACE_CHECK_MOBILE_SESSIONS = "mobile_sessions"
+	NOTIFICATION_TRACE_CHECK_SYSTEM_MESSAGE  = "system_message"
+
+	NOTIFICATION_TRACE_OUTCOME_NOT_SENT = "not_sent"
+	NOTIFICATION_TRACE_OUTCOME_SENT     = "sent"
+	NOTIFICATION_TRACE_OUTCOME_BATCHED  = "batched"
+)
+
+// NotificationTraceStatus says whether the notifications sent to a user are being traced. TTLSeconds is how long the
+// tracing should last when it's turned on, and 0 turns it off.
+type NotificationTraceStatus struct {
+	UserId     string `json:"user_id"`
+	TTLSeconds int64  `json:"ttl_seconds,omitempty"`
+	ExpiresAt  int64  `json:"expires_at"`
+}
+
+func (o *NotificationTraceStatus) IsValid() *AppError {
+	if o.TTLSeconds < 0 || o.TTLSeconds > NOTIFICATION_TRACE_MAX_TTL_SECONDS {
+		return NewAppError("NotificationTraceStatus.IsValid", "model.notification_trace.is_valid.ttl.app_error", map[string]interface{}{"Max": NOTIFICATION_TRACE_MAX_TTL_SECONDS}, "", http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (o *NotificationTraceStatus) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func NotificationTraceStatusFromJson(data io.Reader) *NotificationTraceStatus {
+	var o *NotificationTraceStatus
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
+
+// NotificationTraceStep is one of the checks made before notifying a user. Value is what the check looked at, like the
+// user's preference or status, and Allowed is false if the check stopped the notification from being sent.
+type NotificationTraceStep struct {
+	Type    string `json:"type"`
+	Check   string `json:"check"`
+	Value   string `json:"value"`
+	Allowed bool   `json:"allowed"`
+}
+
+// NotificationTrace records why a user was or wasn't notified about a post. It doesn't include what the post said.
+type NotificationTrace struct {
+	UserId    string                   `json:"user_id"`
+	PostId    string                   `json:"post_id"`
+	ChannelId string                   `json:"channel_id"`
+	CreateAt  int64                    `json:"create_at"`
+	Email     string                   `json:"email"`
+	Push      string                   `json:"push"`
+	Steps     []*NotificationTraceStep `json:"steps"`
+}
+
+func NewNotificationTrace(userId string, post *Post) *NotificationTrace {
+	return &NotificationTrace{
+		UserId:    userId,
+		PostId:    post.Id,
+		ChannelId: post.ChannelId,
+		CreateAt:  GetMillis(),
+		Email:     NOTIFICATION_TRACE_OUTCOME_NOT_SENT,
+		Push:      NOTIFICATION_TRACE_OUTCOME_NOT_SENT,
+		Steps:     []*NotificationTraceStep{},
+	}
+}
+
+// AddStep records a check. It does nothing to a nil trace, so that it can be called whether or not the user's
+// notifications are being traced.
+func (o *NotificationTrace) AddStep(traceType string, check string, value string, allowed bool) {
+	if o == nil {
+		return
+	}
+
+	o.Steps = append(o.Steps, &NotificationTraceStep{
+		Type:    traceType,
+		Check:   check,
+		Value:   value,
+		Allowed: allowed,
+	})
+}
+
+// SetOutcome records whether the notification of the given type was sent. It does nothing to a nil trace.
+func (o *NotificationTrace) SetOutcome(traceType string, outcome string) {
+	if o == nil {
+		return
+	}
+
+	switch traceType {
+	case NOTIFICATION_TRACE_TYPE_EMAIL:
+		o.Email = outcome
+	case NOTIFICATION_TRACE_TYPE_PUSH:
+		o.Push = outcome
+	}
+}
+
+// FindStep returns the first step of the given type and check, or nil if there isn't one.
+func (o *NotificationTrace) FindStep(traceType string, check string) *NotificationTraceStep {
+	for _, step := range o.Steps {
+		if step.Type == traceType && step.Check == check {
+			return step
+		}
+	}
+
+	return nil
+}
+
+func (o *NotificationTrace) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func NotificationTraceFromJson(data io.Reader) *NotificationTrace {
+	var o *NotificationTrace
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
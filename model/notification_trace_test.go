@@ -0,0 +1,43 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationTrace(t *testing.T) {
+	var untraced *NotificationTrace
+	untraced.AddStep(NOTIFICATION_TRACE_TYPE_EMAIL, NOTIFICATION_TRACE_CHECK_ENABLED, "false", false)
+	untraced.SetOutcome(NOTIFICATION_TRACE_TYPE_EMAIL, NOTIFICATION_TRACE_OUTCOME_SENT)
+
+	trace := NewNotificationTrace(NewId(), &Post{Id: NewId(), ChannelId: NewId(), Message: "secret"})
+	assert.Equal(t, NOTIFICATION_TRACE_OUTCOME_NOT_SENT, trace.Email)
+	assert.Equal(t, NOTIFICATION_TRACE_OUTCOME_NOT_SENT, trace.Push)
+
+	trace.AddStep(NOTIFICATION_TRACE_TYPE_PUSH, NOTIFICATION_TRACE_CHECK_STATUS, STATUS_DND, false)
+	trace.SetOutcome(NOTIFICATION_TRACE_TYPE_EMAIL, NOTIFICATION_TRACE_OUTCOME_BATCHED)
+	assert.Equal(t, NOTIFICATION_TRACE_OUTCOME_BATCHED, trace.Email)
+
+	assert.Nil(t, trace.FindStep(NOTIFICATION_TRACE_TYPE_EMAIL, NOTIFICATION_TRACE_CHECK_STATUS))
+	step := trace.FindStep(NOTIFICATION_TRACE_TYPE_PUSH, NOTIFICATION_TRACE_CHECK_STATUS)
+	require.NotNil(t, step)
+	assert.Equal(t, STATUS_DND, step.Value)
+	assert.False(t, step.Allowed)
+
+	json := trace.ToJson()
+	assert.NotContains(t, json, "secret")
+	assert.Equal(t, trace, NotificationTraceFromJson(strings.NewReader(json)))
+}
+
+func TestNotificationTraceStatusIsValid(t *testing.T) {
+	assert.Nil(t, (&NotificationTraceStatus{TTLSeconds: 0}).IsValid())
+	assert.Nil(t, (&NotificationTraceStatus{TTLSeconds: NOTIFICATION_TRACE_MAX_TTL_SECONDS}).IsValid())
+	assert.NotNil(t, (&NotificationTraceStatus{TTLSeconds: -1}).IsValid())
+	assert.NotNil(t, (&NotificationTraceStatus{TTLSeconds: NOTIFICATION_TRACE_MAX_TTL_SECONDS + 1}).IsValid())
+}
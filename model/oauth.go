@@ -17,6 +17,8 @@ const (
 	OAUTH_ACTION_EMAIL_TO_SSO = "email_to_sso"
 	OAUTH_ACTION_SSO_TO_EMAIL = "sso_to_email"
 	OAUTH_ACTION_MOBILE       = "mobile"
+
+	OAUTH_APP_MAX_ACCESS_TOKEN_EXPIRES_IN = 60 * 60 * 24 * 365
 )
 
 type OAuthApp struct {
@@ -31,6 +33,14 @@ type OAuthApp struct {
 	CallbackUrls StringArray `json:"callback_urls"`
 	Homepage     string      `json:"homepage"`
 	IsTrusted    bool        `json:"is_trusted"`
+
+	// AccessTokenExpiresIn is how many seconds the app's access tokens last for, or 0 to use the length of
+	// SSO sessions.
+	AccessTokenExpiresIn int64 `json:"access_token_expires_in"`
+
+	// LegacyRefreshTokens turns off detecting when a refresh token is used after it's been replaced for apps
+	// that were created before refresh tokens were rotated, since they may still rely on reusing them.
+	LegacyRefreshTokens bool `json:"legacy_refresh_tokens"`
 }
 
 // IsValid validates the app and returns an error if it isn't configured
@@ -85,6 +95,10 @@ func (a *OAuthApp) IsValid() *AppError {
 		}
 	}
 
+	if a.AccessTokenExpiresIn < 0 || a.AccessTokenExpiresIn > OAUTH_APP_MAX_ACCESS_TOKEN_EXPIRES_IN {
+		return NewAppError("OAuthApp.IsValid", "model.oauth.is_valid.access_token_expires_in.app_error", map[string]interface{}{"Max": OAUTH_APP_MAX_ACCESS_TOKEN_EXPIRES_IN}, "app_id="+a.Id, http.StatusBadRequest)
+	}
+
 	return nil
 }
 
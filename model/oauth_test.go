@@ -100,4 +100,19 @@ func TestOAuthAppIsValid(t *testing.T) {
 	if err := app.IsValid(); err != nil {
 		t.Fatal()
 	}
+
+	app.AccessTokenExpiresIn = -1
+	if err := app.IsValid(); err == nil {
+		t.Fatal("Should have failed negative access token lifetime")
+	}
+
+	app.AccessTokenExpiresIn = OAUTH_APP_MAX_ACCESS_TOKEN_EXPIRES_IN + 1
+	if err := app.IsValid(); err == nil {
+		t.Fatal("Should have failed access token lifetime too long")
+	}
+
+	app.AccessTokenExpiresIn = 60 * 60
+	if err := app.IsValid(); err != nil {
+		t.Fatal(err)
+	}
 }
@@ -28,10 +28,18 @@ type OutgoingWebhook struct {
 	DisplayName  string      `json:"display_name"`
 	Description  string      `json:"description"`
 	ContentType  string      `json:"content_type"`
+
+	// SigningSecret, if set, signs the requests sent to the callback URLs with SigningAlgorithm. See
+	// SignIntegrationRequest.
+	SigningSecret    string `json:"signing_secret"`
+	SigningAlgorithm string `json:"signing_algorithm"`
+
+	// DisableLegacyToken leaves Token out of the requests, for receivers that verify their signatures instead.
+	DisableLegacyToken bool `json:"disable_legacy_token"`
 }
 
 type OutgoingWebhookPayload struct {
-	Token       string `json:"token"`
+	Token       string `json:"token,omitempty"`
 	TeamId      string `json:"team_id"`
 	TeamDomain  string `json:"team_domain"`
 	ChannelId   string `json:"channel_id"`
@@ -64,7 +72,9 @@ func (o *OutgoingWebhookPayload) ToJSON() string {
 
 func (o *OutgoingWebhookPayload) ToFormValues() string {
 	v := url.Values{}
-	v.Set("token", o.Token)
+	if o.Token != "" {
+		v.Set("token", o.Token)
+	}
 	v.Set("team_id", o.TeamId)
 	v.Set("team_domain", o.TeamDomain)
 	v.Set("channel_id", o.ChannelId)
@@ -181,6 +191,14 @@ func (o *OutgoingWebhook) IsValid() *AppError {
 		return NewAppError("OutgoingWebhook.IsValid", "model.outgoing_hook.is_valid.content_type.app_error", nil, "", http.StatusBadRequest)
 	}
 
+	if !IsValidIntegrationSigningAlgorithm(o.SigningAlgorithm) {
+		return NewAppError("OutgoingWebhook.IsValid", "model.outgoing_hook.is_valid.signing_algorithm.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if o.DisableLegacyToken && o.SigningSecret == "" {
+		return NewAppError("OutgoingWebhook.IsValid", "model.outgoing_hook.is_valid.disable_legacy_token.app_error", nil, "", http.StatusBadRequest)
+	}
+
 	return nil
 }
 
@@ -193,6 +211,10 @@ func (o *OutgoingWebhook) PreSave() {
 		o.Token = NewId()
 	}
 
+	if o.SigningSecret == "" {
+		o.SigningSecret = NewRandomString(INTEGRATION_SIGNING_SECRET_LENGTH)
+	}
+
 	o.CreateAt = GetMillis()
 	o.UpdateAt = o.CreateAt
 }
@@ -8,6 +8,8 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestOutgoingWebhookJson(t *testing.T) {
@@ -121,6 +123,26 @@ func TestOutgoingWebhookIsValid(t *testing.T) {
 	if err := o.IsValid(); err != nil {
 		t.Fatal(err)
 	}
+
+	o.SigningAlgorithm = "md5"
+	if err := o.IsValid(); err == nil {
+		t.Fatal("should be invalid")
+	}
+
+	o.SigningAlgorithm = INTEGRATION_SIGNING_ALGORITHM_SHA512
+	if err := o.IsValid(); err != nil {
+		t.Fatal(err)
+	}
+
+	o.DisableLegacyToken = true
+	if err := o.IsValid(); err == nil {
+		t.Fatal("should be invalid without a signing secret")
+	}
+
+	o.SigningSecret = NewRandomString(INTEGRATION_SIGNING_SECRET_LENGTH)
+	if err := o.IsValid(); err != nil {
+		t.Fatal(err)
+	}
 }
 
 func TestOutgoingWebhookPayloadToFormValues(t *testing.T) {
@@ -156,9 +178,18 @@ func TestOutgoingWebhookPayloadToFormValues(t *testing.T) {
 	}
 }
 
+func TestOutgoingWebhookPayloadWithoutToken(t *testing.T) {
+	p := &OutgoingWebhookPayload{TeamId: "TeamId"}
+
+	assert.NotContains(t, p.ToFormValues(), "token")
+	assert.NotContains(t, p.ToJSON(), "token")
+}
+
 func TestOutgoingWebhookPreSave(t *testing.T) {
 	o := OutgoingWebhook{}
 	o.PreSave()
+
+	assert.Len(t, o.SigningSecret, INTEGRATION_SIGNING_SECRET_LENGTH)
 }
 
 func TestOutgoingWebhookPreUpdate(t *testing.T) {
@@ -67,6 +67,13 @@ var PERMISSION_MANAGE_JOBS *Permission
 var PERMISSION_CREATE_USER_ACCESS_TOKEN *Permission
 var PERMISSION_READ_USER_ACCESS_TOKEN *Permission
 var PERMISSION_REVOKE_USER_ACCESS_TOKEN *Permission
+var PERMISSION_MANAGE_CUSTOM_GROUPS *Permission
+var PERMISSION_INVITE_GUEST *Permission
+var PERMISSION_IMPERSONATE_USER *Permission
+var PERMISSION_READ_SYSTEM_CONSOLE *Permission
+var PERMISSION_MANAGE_USERS *Permission
+var PERMISSION_PROMOTE_GUEST *Permission
+var PERMISSION_DEMOTE_TO_GUEST *Permission
 
 // General permission that encompasses all system admin functions
 // in the future this could be broken up to allow access to some
@@ -382,6 +389,48 @@ func initializePermissions() {
 		"authentication.permissions.revoke_user_access_token.description",
 		PERMISSION_SCOPE_SYSTEM,
 	}
+	PERMISSION_MANAGE_CUSTOM_GROUPS = &Permission{
+		"manage_custom_groups",
+		"authentication.permissions.manage_custom_groups.name",
+		"authentication.permissions.manage_custom_groups.description",
+		PERMISSION_SCOPE_SYSTEM,
+	}
+	PERMISSION_INVITE_GUEST = &Permission{
+		"invite_guest",
+		"authentication.permissions.invite_guest.name",
+		"authentication.permissions.invite_guest.description",
+		PERMISSION_SCOPE_TEAM,
+	}
+	PERMISSION_PROMOTE_GUEST = &Permission{
+		"promote_guest",
+		"authentication.permissions.promote_guest.name",
+		"authentication.permissions.promote_guest.description",
+		PERMISSION_SCOPE_SYSTEM,
+	}
+	PERMISSION_DEMOTE_TO_GUEST = &Permission{
+		"demote_to_guest",
+		"authentication.permissions.demote_to_guest.name",
+		"authentication.permissions.demote_to_guest.description",
+		PERMISSION_SCOPE_SYSTEM,
+	}
+	PERMISSION_IMPERSONATE_USER = &Permission{
+		"impersonate_user",
+		"authentication.permissions.impersonate_user.name",
+		"authentication.permissions.impersonate_user.description",
+		PERMISSION_SCOPE_SYSTEM,
+	}
+	PERMISSION_READ_SYSTEM_CONSOLE = &Permission{
+		"read_system_console",
+		"authentication.permissions.read_system_console.name",
+		"authentication.permissions.read_system_console.description",
+		PERMISSION_SCOPE_SYSTEM,
+	}
+	PERMISSION_MANAGE_USERS = &Permission{
+		"manage_users",
+		"authentication.permissions.manage_users.name",
+		"authentication.permissions.manage_users.description",
+		PERMISSION_SCOPE_SYSTEM,
+	}
 	PERMISSION_MANAGE_JOBS = &Permission{
 		"manage_jobs",
 		"authentication.permisssions.manage_jobs.name",
@@ -441,6 +490,13 @@ func initializePermissions() {
 		PERMISSION_CREATE_USER_ACCESS_TOKEN,
 		PERMISSION_READ_USER_ACCESS_TOKEN,
 		PERMISSION_REVOKE_USER_ACCESS_TOKEN,
+		PERMISSION_MANAGE_CUSTOM_GROUPS,
+		PERMISSION_INVITE_GUEST,
+		PERMISSION_PROMOTE_GUEST,
+		PERMISSION_DEMOTE_TO_GUEST,
+		PERMISSION_IMPERSONATE_USER,
+		PERMISSION_READ_SYSTEM_CONSOLE,
+		PERMISSION_MANAGE_USERS,
 		PERMISSION_MANAGE_SYSTEM,
 	}
 }
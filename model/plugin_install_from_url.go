@@ -0,0 +1,43 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// InstallPluginFromURLRequest asks the server to download a plugin bundle and install it once its SHA-256 checksum
+// has been verified.
+type InstallPluginFromURLRequest struct {
+	URL      string `json:"url"`
+	SHA256   string `json:"sha256"`
+	Activate bool   `json:"activate"`
+}
+
+func (r *InstallPluginFromURLRequest) IsValid() *AppError {
+	if u, err := url.Parse(r.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return NewAppError("InstallPluginFromURLRequest.IsValid", "model.plugin_install_from_url.is_valid.url.app_error", nil, "url="+r.URL, http.StatusBadRequest)
+	}
+
+	if b, err := hex.DecodeString(r.SHA256); err != nil || len(b) != 32 {
+		return NewAppError("InstallPluginFromURLRequest.IsValid", "model.plugin_install_from_url.is_valid.sha256.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (r *InstallPluginFromURLRequest) ToJson() string {
+	b, _ := json.Marshal(r)
+	return string(b)
+}
+
+func InstallPluginFromURLRequestFromJson(data io.Reader) *InstallPluginFromURLRequest {
+	var r *InstallPluginFromURLRequest
+	json.NewDecoder(data).Decode(&r)
+	return r
+}
@@ -0,0 +1,32 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstallPluginFromURLRequestIsValid(t *testing.T) {
+	checksum := strings.Repeat("ab", 32)
+
+	assert.Nil(t, (&InstallPluginFromURLRequest{URL: "https://example.com/plugin.tar.gz", SHA256: checksum}).IsValid())
+	assert.Nil(t, (&InstallPluginFromURLRequest{URL: "http://internal:8080/plugin.tar.gz", SHA256: strings.ToUpper(checksum)}).IsValid())
+
+	assert.NotNil(t, (&InstallPluginFromURLRequest{URL: "", SHA256: checksum}).IsValid())
+	assert.NotNil(t, (&InstallPluginFromURLRequest{URL: "/plugin.tar.gz", SHA256: checksum}).IsValid())
+	assert.NotNil(t, (&InstallPluginFromURLRequest{URL: "file:///tmp/plugin.tar.gz", SHA256: checksum}).IsValid())
+	assert.NotNil(t, (&InstallPluginFromURLRequest{URL: "https://example.com/plugin.tar.gz", SHA256: ""}).IsValid())
+	assert.NotNil(t, (&InstallPluginFromURLRequest{URL: "https://example.com/plugin.tar.gz", SHA256: checksum[:62]}).IsValid())
+	assert.NotNil(t, (&InstallPluginFromURLRequest{URL: "https://example.com/plugin.tar.gz", SHA256: strings.Repeat("zz", 32)}).IsValid())
+}
+
+func TestInstallPluginFromURLRequestJson(t *testing.T) {
+	request := &InstallPluginFromURLRequest{URL: "https://example.com/plugin.tar.gz", SHA256: strings.Repeat("ab", 32), Activate: true}
+
+	assert.Equal(t, request, InstallPluginFromURLRequestFromJson(strings.NewReader(request.ToJson())))
+	assert.Nil(t, InstallPluginFromURLRequestFromJson(strings.NewReader("junk")))
+}
@@ -37,6 +37,7 @@ const (
 	POST_CONVERT_CHANNEL        = "system_convert_channel"
 	POST_PURPOSE_CHANGE         = "system_purpose_change"
 	POST_CHANNEL_DELETED        = "system_channel_deleted"
+	POST_CHANNEL_INACTIVE       = "system_channel_inactive"
 	POST_EPHEMERAL              = "system_ephemeral"
 	POST_CHANGE_CHANNEL_PRIVACY = "system_change_chan_privacy"
 	POST_FILEIDS_MAX_RUNES      = 150
@@ -48,8 +49,12 @@ const (
 	POST_PROPS_MAX_RUNES        = 8000
 	POST_PROPS_MAX_USER_RUNES   = POST_PROPS_MAX_RUNES - 400 // Leave some room for system / pre-save modifications
 	POST_CUSTOM_TYPE_PREFIX     = "custom_"
+	POST_PROPS_RESERVED_PREFIX  = "mm_"
 	PROPS_ADD_CHANNEL_MEMBER    = "add_channel_member"
 	POST_PROPS_ADDED_USER_ID    = "addedUserId"
+
+	// POST_PROPS_WEBHOOK_ID is the incoming webhook that created a post, which is the only one that can change it
+	POST_PROPS_WEBHOOK_ID = "mm_webhook_id"
 )
 
 type Post struct {
@@ -79,6 +84,28 @@ type Post struct {
 	FileIds       StringArray     `json:"file_ids,omitempty"`
 	PendingPostId string          `json:"pending_post_id" db:"-"`
 	HasReactions  bool            `json:"has_reactions,omitempty"`
+
+	// Metadata is filled in for the user that the post is being sent to and is never stored.
+	Metadata *PostMetadata `json:"metadata,omitempty" db:"-"`
+
+	// RequestId is the id of the API request that created the post so that it can be passed along to outgoing
+	// webhooks. It's never stored or sent to clients.
+	RequestId string `json:"-" db:"-"`
+}
+
+// reservedPostProps are the props using POST_PROPS_RESERVED_PREFIX that the server knows about. Any other props
+// with the prefix are removed when a post is saved so that they can be given a meaning later.
+var reservedPostProps = map[string]bool{
+	POST_PROPS_WEBHOOK_ID: true,
+}
+
+// PostPropsSize describes how large a post's props are once they've been serialized.
+type PostPropsSize struct {
+	PostId    string `json:"post_id"`
+	ChannelId string `json:"channel_id"`
+	UserId    string `json:"user_id"`
+	CreateAt  int64  `json:"create_at"`
+	Size      int    `json:"size"`
 }
 
 type PostEphemeral struct {
@@ -112,6 +139,19 @@ type PostAction struct {
 	Id          string                 `json:"id"`
 	Name        string                 `json:"name"`
 	Integration *PostActionIntegration `json:"integration,omitempty"`
+
+	// Confirm, if set, is shown to the user to confirm the action before it's performed.
+	Confirm *PostActionConfirm `json:"confirm,omitempty"`
+
+	// Disabled actions are still shown, but can no longer be performed.
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+type PostActionConfirm struct {
+	Title       string `json:"title"`
+	Message     string `json:"message"`
+	ConfirmText string `json:"confirm_text,omitempty"`
+	CancelText  string `json:"cancel_text,omitempty"`
 }
 
 type PostActionIntegration struct {
@@ -120,13 +160,30 @@ type PostActionIntegration struct {
 }
 
 type PostActionIntegrationRequest struct {
-	UserId  string          `json:"user_id"`
-	Context StringInterface `json:"context,omitempty"`
+	UserId    string          `json:"user_id"`
+	PostId    string          `json:"post_id"`
+	ChannelId string          `json:"channel_id"`
+	TeamId    string          `json:"team_id"`
+	ActionId  string          `json:"action_id"`
+	Context   StringInterface `json:"context,omitempty"`
+
+	// RequestId and Timestamp are signed along with the rest of the request so that the integration can reject
+	// requests that it has already seen or that are too old.
+	RequestId string `json:"request_id"`
+	Timestamp int64  `json:"timestamp"`
 }
 
 type PostActionIntegrationResponse struct {
 	Update        *Post  `json:"update"`
 	EphemeralText string `json:"ephemeral_text"`
+
+	// Props are set on the post when it isn't replaced by Update, with null values removing props. Setting the
+	// attachments replaces the post's actions.
+	Props StringInterface `json:"props,omitempty"`
+
+	// DisableActions lists the ids of the actions on the post to disable, or POST_ACTION_ALL to disable all of
+	// them.
+	DisableActions []string `json:"disable_actions,omitempty"`
 }
 
 func (o *Post) ToJson() string {
@@ -136,6 +193,64 @@ func (o *Post) ToJson() string {
 	return string(b)
 }
 
+// POST_SPARSE_FIELDS are the fields of a post that clients can ask for on their own with the fields query parameter.
+var POST_SPARSE_FIELDS = map[string]bool{
+	"id": true, "create_at": true, "update_at": true, "edit_at": true, "delete_at": true, "is_pinned": true,
+	"user_id": true, "channel_id": true, "root_id": true, "parent_id": true, "message": true, "type": true,
+	"props": true, "file_ids": true, "has_reactions": true, "metadata": true,
+}
+
+// ToSparseMap returns only the given fields of the post for serializing. Fields that aren't in POST_SPARSE_FIELDS are
+// left out, and fields that are empty are left out where they would be by ToJson. Action integrations must already have
+// been stripped from the post.
+func (o *Post) ToSparseMap(fields []string) map[string]interface{} {
+	m := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		switch field {
+		case "id":
+			m[field] = o.Id
+		case "create_at":
+			m[field] = o.CreateAt
+		case "update_at":
+			m[field] = o.UpdateAt
+		case "edit_at":
+			m[field] = o.EditAt
+		case "delete_at":
+			m[field] = o.DeleteAt
+		case "is_pinned":
+			m[field] = o.IsPinned
+		case "user_id":
+			m[field] = o.UserId
+		case "channel_id":
+			m[field] = o.ChannelId
+		case "root_id":
+			m[field] = o.RootId
+		case "parent_id":
+			m[field] = o.ParentId
+		case "message":
+			m[field] = o.Message
+		case "type":
+			m[field] = o.Type
+		case "props":
+			m[field] = o.Props
+		case "file_ids":
+			if len(o.FileIds) > 0 {
+				m[field] = o.FileIds
+			}
+		case "has_reactions":
+			if o.HasReactions {
+				m[field] = o.HasReactions
+			}
+		case "metadata":
+			if o.Metadata != nil {
+				m[field] = o.Metadata
+			}
+		}
+	}
+
+	return m
+}
+
 func (o *Post) ToUnsanitizedJson() string {
 	b, _ := json.Marshal(o)
 	return string(b)
@@ -218,6 +333,7 @@ func (o *Post) IsValid(maxPostSize int) *AppError {
 		POST_DISPLAYNAME_CHANGE,
 		POST_CONVERT_CHANNEL,
 		POST_CHANNEL_DELETED,
+		POST_CHANNEL_INACTIVE,
 		POST_CHANGE_CHANNEL_PRIVACY:
 	default:
 		if !strings.HasPrefix(o.Type, POST_CUSTOM_TYPE_PREFIX) {
@@ -243,6 +359,7 @@ func (o *Post) IsValid(maxPostSize int) *AppError {
 func (o *Post) SanitizeProps() {
 	membersToSanitize := []string{
 		PROPS_ADD_CHANNEL_MEMBER,
+		POST_PROPS_FORWARDED_POST,
 	}
 
 	for _, member := range membersToSanitize {
@@ -250,6 +367,17 @@ func (o *Post) SanitizeProps() {
 			delete(o.Props, member)
 		}
 	}
+
+	for key := range o.Props {
+		if strings.HasPrefix(key, POST_PROPS_RESERVED_PREFIX) && !reservedPostProps[key] {
+			delete(o.Props, key)
+		}
+	}
+}
+
+// PropsSize returns the size in bytes of the post's props once they've been serialized.
+func (o *Post) PropsSize() int {
+	return len(StringInterfaceToJson(o.Props))
 }
 
 func (o *Post) PreSave() {
@@ -363,6 +491,11 @@ func (r *PostActionIntegrationRequest) ToJson() string {
 	return string(b)
 }
 
+// IsEmpty returns true if the post has no message, files or message attachments, so there's nothing to show for it.
+func (o *Post) IsEmpty() bool {
+	return strings.TrimSpace(o.Message) == "" && len(o.FileIds) == 0 && len(o.Attachments()) == 0
+}
+
 func (o *Post) Attachments() []*SlackAttachment {
 	if attachments, ok := o.Props["attachments"].([]*SlackAttachment); ok {
 		return attachments
@@ -404,6 +537,27 @@ func (o *Post) GetAction(id string) *PostAction {
 	return nil
 }
 
+// DisableActions disables the post's actions with the given ids, or all of them if the ids include POST_ACTION_ALL.
+func (o *Post) DisableActions(ids []string) {
+	disabled := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		disabled[id] = true
+	}
+
+	attachments := o.Attachments()
+	for _, attachment := range attachments {
+		for _, action := range attachment.Actions {
+			if disabled[POST_ACTION_ALL] || disabled[action.Id] {
+				action.Disabled = true
+			}
+		}
+	}
+
+	if o.Props["attachments"] != nil {
+		o.Props["attachments"] = attachments
+	}
+}
+
 func (o *Post) GenerateActionIds() {
 	if o.Props["attachments"] != nil {
 		o.Props["attachments"] = o.Attachments()
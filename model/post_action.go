@@ -0,0 +1,72 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// POST_ACTION_ALL stands in for every action on a post when disabling them.
+	POST_ACTION_ALL = "*"
+
+	HEADER_POST_ACTION_SIGNATURE = "X-Mattermost-Signature"
+	POST_ACTION_SIGNATURE_PREFIX = "v1="
+
+	// POST_ACTION_REQUEST_MAX_AGE is how long, in milliseconds, a signed post action request should be accepted
+	// for after it's sent.
+	POST_ACTION_REQUEST_MAX_AGE = 5 * 60 * 1000
+)
+
+// PostActionSigningSecretForURL returns the secret that the requests to the post action integration at
+// integrationURL are signed with. It's derived from the server's post action signing secret and the scheme and host
+// of the URL, so each integration has its own secret and can't sign requests that another one would accept.
+func PostActionSigningSecretForURL(serverSecret string, integrationURL string) (string, *AppError) {
+	parsed, err := url.Parse(integrationURL)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return "", NewAppError("PostActionSigningSecretForURL", "model.post_action.signing_secret.url.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	mac := hmac.New(sha256.New, []byte(serverSecret))
+	mac.Write([]byte("post_action:" + strings.ToLower(parsed.Scheme+"://"+parsed.Host)))
+
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// SignPostActionIntegrationRequest returns the signature sent with a post action request in the
+// X-Mattermost-Signature header, which is an HMAC-SHA256 of the request body keyed by the integration's secret from
+// PostActionSigningSecretForURL.
+func SignPostActionIntegrationRequest(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return POST_ACTION_SIGNATURE_PREFIX + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyPostActionIntegrationRequest is used by integrations to check that a post action request was signed with
+// the given secret and was sent no more than POST_ACTION_REQUEST_MAX_AGE before now, the current time in
+// milliseconds. To fully prevent a request from being replayed, the integration should also reject request ids
+// that it's already seen within that time.
+func VerifyPostActionIntegrationRequest(body []byte, signature string, secret string, now int64) (*PostActionIntegrationRequest, *AppError) {
+	if !strings.HasPrefix(signature, POST_ACTION_SIGNATURE_PREFIX) || !hmac.Equal([]byte(signature), []byte(SignPostActionIntegrationRequest(body, secret))) {
+		return nil, NewAppError("VerifyPostActionIntegrationRequest", "model.post_action.verify.signature.app_error", nil, "", http.StatusUnauthorized)
+	}
+
+	var request *PostActionIntegrationRequest
+	if err := json.Unmarshal(body, &request); err != nil || request == nil {
+		return nil, NewAppError("VerifyPostActionIntegrationRequest", "model.post_action.verify.decode.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if request.Timestamp > now+POST_ACTION_REQUEST_MAX_AGE || now-request.Timestamp > POST_ACTION_REQUEST_MAX_AGE {
+		return nil, NewAppError("VerifyPostActionIntegrationRequest", "model.post_action.verify.expired.app_error", nil, "request_id="+request.RequestId, http.StatusUnauthorized)
+	}
+
+	return request, nil
+}
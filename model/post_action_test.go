@@ -0,0 +1,68 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostActionSigningSecretForURL(t *testing.T) {
+	serverSecret := NewRandomString(32)
+
+	secret, err := PostActionSigningSecretForURL(serverSecret, "https://example.com/actions/a")
+	require.Nil(t, err)
+	assert.Len(t, secret, 64)
+	assert.NotContains(t, secret, serverSecret)
+
+	same, err := PostActionSigningSecretForURL(serverSecret, "HTTPS://Example.com/actions/b?x=1")
+	require.Nil(t, err)
+	assert.Equal(t, secret, same, "every action handled by an integration is signed with the same secret")
+
+	for _, other := range []string{"https://example.org/actions/a", "http://example.com/actions/a", "https://example.com:8443/actions/a"} {
+		otherSecret, err := PostActionSigningSecretForURL(serverSecret, other)
+		require.Nil(t, err)
+		assert.NotEqual(t, secret, otherSecret, other)
+	}
+
+	otherServer, err := PostActionSigningSecretForURL(NewRandomString(32), "https://example.com/actions/a")
+	require.Nil(t, err)
+	assert.NotEqual(t, secret, otherServer)
+
+	for _, invalid := range []string{"", "/actions/a", "example.com", "://example.com"} {
+		_, err := PostActionSigningSecretForURL(serverSecret, invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestVerifyPostActionIntegrationRequest(t *testing.T) {
+	secret := NewRandomString(32)
+	now := GetMillis()
+
+	request := &PostActionIntegrationRequest{UserId: NewId(), PostId: NewId(), ActionId: NewId(), RequestId: NewId(), Timestamp: now}
+	body := []byte(request.ToJson())
+	signature := SignPostActionIntegrationRequest(body, secret)
+
+	verified, err := VerifyPostActionIntegrationRequest(body, signature, secret, now)
+	require.Nil(t, err)
+	assert.Equal(t, request, verified)
+
+	_, err = VerifyPostActionIntegrationRequest(body, signature, NewRandomString(32), now)
+	require.NotNil(t, err, "should reject requests signed with another secret")
+	assert.Equal(t, "model.post_action.verify.signature.app_error", err.Id)
+
+	_, err = VerifyPostActionIntegrationRequest(body, "", secret, now)
+	require.NotNil(t, err)
+
+	tampered := *request
+	tampered.UserId = NewId()
+	_, err = VerifyPostActionIntegrationRequest([]byte(tampered.ToJson()), signature, secret, now)
+	require.NotNil(t, err, "should reject requests that were changed after being signed")
+
+	_, err = VerifyPostActionIntegrationRequest(body, signature, secret, now+POST_ACTION_REQUEST_MAX_AGE+1)
+	require.NotNil(t, err, "should reject requests that are replayed too late")
+	assert.Equal(t, "model.post_action.verify.expired.app_error", err.Id)
+}
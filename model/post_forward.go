@@ -0,0 +1,67 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+)
+
+const (
+	POST_PROPS_FORWARDED_POST      = "forwarded_post"
+	POST_FORWARD_EXCERPT_MAX_RUNES = 300
+)
+
+// PostForward is a request to share an existing post in another channel along with an optional comment.
+type PostForward struct {
+	ChannelId string `json:"channel_id"`
+	Message   string `json:"message"`
+}
+
+// ForwardedPostPreview is stored in the props of a forwarded post to describe the post that it shares.
+// When some of the channel that it was shared to can't read the original post, only the post id and
+// permalink are kept.
+type ForwardedPostPreview struct {
+	PostId             string `json:"post_id"`
+	Permalink          string `json:"permalink"`
+	UserId             string `json:"user_id,omitempty"`
+	Username           string `json:"username,omitempty"`
+	ChannelId          string `json:"channel_id,omitempty"`
+	ChannelDisplayName string `json:"channel_display_name,omitempty"`
+	CreateAt           int64  `json:"create_at,omitempty"`
+	Excerpt            string `json:"excerpt,omitempty"`
+}
+
+func (o *PostForward) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func PostForwardFromJson(data io.Reader) *PostForward {
+	var o *PostForward
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
+
+// NewForwardedPostPreview returns a preview of post for a message forwarded to a channel that can read it.
+func NewForwardedPostPreview(post *Post, author *User, channel *Channel, permalink string) *ForwardedPostPreview {
+	return &ForwardedPostPreview{
+		PostId:             post.Id,
+		Permalink:          permalink,
+		UserId:             author.Id,
+		Username:           author.Username,
+		ChannelId:          channel.Id,
+		ChannelDisplayName: channel.DisplayName,
+		CreateAt:           post.CreateAt,
+		Excerpt:            TruncateRunes(post.Message, POST_FORWARD_EXCERPT_MAX_RUNES),
+	}
+}
+
+// ToMap returns the preview in the form that it's stored in post props.
+func (p *ForwardedPostPreview) ToMap() map[string]interface{} {
+	var m map[string]interface{}
+	b, _ := json.Marshal(p)
+	json.Unmarshal(b, &m)
+	return m
+}
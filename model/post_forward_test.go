@@ -0,0 +1,45 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostForwardJson(t *testing.T) {
+	o := PostForward{ChannelId: NewId(), Message: "comment"}
+	ro := PostForwardFromJson(strings.NewReader(o.ToJson()))
+
+	assert.Equal(t, o, *ro)
+	assert.Nil(t, PostForwardFromJson(strings.NewReader("junk")))
+}
+
+func TestNewForwardedPostPreview(t *testing.T) {
+	author := &User{Id: NewId(), Username: "author"}
+	channel := &Channel{Id: NewId(), DisplayName: "Channel"}
+	post := &Post{Id: NewId(), ChannelId: channel.Id, UserId: author.Id, Message: "message", CreateAt: 1234}
+
+	preview := NewForwardedPostPreview(post, author, channel, "http://localhost/team/pl/"+post.Id)
+	assert.Equal(t, map[string]interface{}{
+		"post_id":              post.Id,
+		"permalink":            "http://localhost/team/pl/" + post.Id,
+		"user_id":              author.Id,
+		"username":             "author",
+		"channel_id":           channel.Id,
+		"channel_display_name": "Channel",
+		"create_at":            float64(1234),
+		"excerpt":              "message",
+	}, preview.ToMap())
+
+	post.Message = strings.Repeat("ü", POST_FORWARD_EXCERPT_MAX_RUNES+1)
+	preview = NewForwardedPostPreview(post, author, channel, "")
+	assert.Equal(t, strings.Repeat("ü", POST_FORWARD_EXCERPT_MAX_RUNES-1)+"…", preview.Excerpt)
+
+	post.Message = strings.Repeat("ü", POST_FORWARD_EXCERPT_MAX_RUNES)
+	preview = NewForwardedPostPreview(post, author, channel, "")
+	assert.Equal(t, post.Message, preview.Excerpt)
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"unicode/utf8"
+)
+
+// PostHistory is a version of a post from before it was edited. EditorId and EditAt describe the edit
+// that replaced it.
+type PostHistory struct {
+	Id        string          `json:"id"`
+	PostId    string          `json:"post_id"`
+	ChannelId string          `json:"channel_id"`
+	Message   string          `json:"message"`
+	Props     StringInterface `json:"props"`
+	EditorId  string          `json:"editor_id"`
+	EditAt    int64           `json:"edit_at"`
+}
+
+// NewPostHistory returns the history entry recording that post was edited by editorId at editAt.
+func NewPostHistory(post *Post, editorId string, editAt int64) *PostHistory {
+	props := make(StringInterface, len(post.Props))
+	for key, value := range post.Props {
+		props[key] = value
+	}
+
+	return &PostHistory{
+		PostId:    post.Id,
+		ChannelId: post.ChannelId,
+		Message:   post.Message,
+		Props:     props,
+		EditorId:  editorId,
+		EditAt:    editAt,
+	}
+}
+
+func (h *PostHistory) IsValid(maxPostSize int) *AppError {
+	if len(h.Id) != 26 {
+		return NewAppError("PostHistory.IsValid", "model.post_history.is_valid.id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if len(h.PostId) != 26 {
+		return NewAppError("PostHistory.IsValid", "model.post_history.is_valid.post_id.app_error", nil, "id="+h.Id, http.StatusBadRequest)
+	}
+
+	if len(h.ChannelId) != 26 {
+		return NewAppError("PostHistory.IsValid", "model.post_history.is_valid.channel_id.app_error", nil, "id="+h.Id, http.StatusBadRequest)
+	}
+
+	if len(h.EditorId) != 26 {
+		return NewAppError("PostHistory.IsValid", "model.post_history.is_valid.editor_id.app_error", nil, "id="+h.Id, http.StatusBadRequest)
+	}
+
+	if h.EditAt == 0 {
+		return NewAppError("PostHistory.IsValid", "model.post_history.is_valid.edit_at.app_error", nil, "id="+h.Id, http.StatusBadRequest)
+	}
+
+	if utf8.RuneCountInString(h.Message) > maxPostSize {
+		return NewAppError("PostHistory.IsValid", "model.post_history.is_valid.msg.app_error", nil, "id="+h.Id, http.StatusBadRequest)
+	}
+
+	if utf8.RuneCountInString(StringInterfaceToJson(h.Props)) > POST_PROPS_MAX_RUNES {
+		return NewAppError("PostHistory.IsValid", "model.post_history.is_valid.props.app_error", nil, "id="+h.Id, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (h *PostHistory) PreSave() {
+	if h.Id == "" {
+		h.Id = NewId()
+	}
+
+	if h.Props == nil {
+		h.Props = make(map[string]interface{})
+	}
+}
+
+func PostHistoryListToJson(l []*PostHistory) string {
+	b, _ := json.Marshal(l)
+	return string(b)
+}
+
+func PostHistoryListFromJson(data io.Reader) []*PostHistory {
+	var l []*PostHistory
+	json.NewDecoder(data).Decode(&l)
+	return l
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPostHistory(t *testing.T) {
+	post := &Post{Id: NewId(), ChannelId: NewId(), Message: "original", Props: StringInterface{"attachments": "a"}}
+
+	history := NewPostHistory(post, NewId(), 1234)
+	assert.Equal(t, post.Id, history.PostId)
+	assert.Equal(t, post.ChannelId, history.ChannelId)
+	assert.Equal(t, "original", history.Message)
+	assert.Equal(t, int64(1234), history.EditAt)
+
+	post.Props["attachments"] = "b"
+	assert.Equal(t, "a", history.Props["attachments"], "should copy the post's props")
+}
+
+func TestPostHistoryIsValid(t *testing.T) {
+	history := NewPostHistory(&Post{Id: NewId(), ChannelId: NewId(), Message: "message"}, NewId(), GetMillis())
+	history.PreSave()
+	require.Nil(t, history.IsValid(POST_MESSAGE_MAX_RUNES_V2))
+
+	for name, modify := range map[string]func(h *PostHistory){
+		"id":         func(h *PostHistory) { h.Id = "" },
+		"post_id":    func(h *PostHistory) { h.PostId = "junk" },
+		"channel_id": func(h *PostHistory) { h.ChannelId = "" },
+		"editor_id":  func(h *PostHistory) { h.EditorId = "" },
+		"edit_at":    func(h *PostHistory) { h.EditAt = 0 },
+		"msg":        func(h *PostHistory) { h.Message = strings.Repeat("a", POST_MESSAGE_MAX_RUNES_V2+1) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			invalid := *history
+			modify(&invalid)
+
+			err := invalid.IsValid(POST_MESSAGE_MAX_RUNES_V2)
+			require.NotNil(t, err)
+			assert.Equal(t, "model.post_history.is_valid."+name+".app_error", err.Id)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"net/http"
+)
+
+const (
+	POST_IDEMPOTENCY_KEY_MAX_LENGTH = 64
+)
+
+// PostIdempotencyKey records the post that was created for a pending post id sent by a client, so that a retry of
+// the same request gets that post back instead of creating another one. PostId is empty while the post is still
+// being created.
+type PostIdempotencyKey struct {
+	UserId         string `json:"user_id"`
+	IdempotencyKey string `json:"idempotency_key"`
+	PostId         string `json:"post_id"`
+	CreateAt       int64  `json:"create_at"`
+}
+
+func (o *PostIdempotencyKey) PreSave() {
+	if o.CreateAt == 0 {
+		o.CreateAt = GetMillis()
+	}
+}
+
+func (o *PostIdempotencyKey) IsValid() *AppError {
+	if !IsValidId(o.UserId) {
+		return NewAppError("PostIdempotencyKey.IsValid", "model.post_idempotency_key.is_valid.user_id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if o.IdempotencyKey == "" || len(o.IdempotencyKey) > POST_IDEMPOTENCY_KEY_MAX_LENGTH {
+		return NewAppError("PostIdempotencyKey.IsValid", "model.post_idempotency_key.is_valid.idempotency_key.app_error", map[string]interface{}{"MaxLength": POST_IDEMPOTENCY_KEY_MAX_LENGTH}, "user_id="+o.UserId, http.StatusBadRequest)
+	}
+
+	if o.PostId != "" && !IsValidId(o.PostId) {
+		return NewAppError("PostIdempotencyKey.IsValid", "model.post_idempotency_key.is_valid.post_id.app_error", nil, "user_id="+o.UserId, http.StatusBadRequest)
+	}
+
+	if o.CreateAt == 0 {
+		return NewAppError("PostIdempotencyKey.IsValid", "model.post_idempotency_key.is_valid.create_at.app_error", nil, "user_id="+o.UserId, http.StatusBadRequest)
+	}
+
+	return nil
+}
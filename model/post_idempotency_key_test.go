@@ -0,0 +1,27 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostIdempotencyKeyIsValid(t *testing.T) {
+	key := &PostIdempotencyKey{UserId: NewId(), IdempotencyKey: NewId() + ":1"}
+	key.PreSave()
+	assert.Nil(t, key.IsValid())
+
+	key.PostId = "invalid"
+	assert.NotNil(t, key.IsValid())
+	key.PostId = NewId()
+	assert.Nil(t, key.IsValid())
+
+	key.IdempotencyKey = strings.Repeat("a", POST_IDEMPOTENCY_KEY_MAX_LENGTH+1)
+	assert.NotNil(t, key.IsValid())
+	key.IdempotencyKey = ""
+	assert.NotNil(t, key.IsValid())
+}
@@ -51,6 +51,28 @@ func (o *PostList) ToJson() string {
 	}
 }
 
+// ToSparseJson serializes only the given fields of each post, or all of them if no fields are given.
+func (o *PostList) ToSparseJson(fields []string) string {
+	if len(fields) == 0 {
+		return o.ToJson()
+	}
+
+	copy := *o
+	copy.StripActionIntegrations()
+
+	posts := make(map[string]map[string]interface{}, len(copy.Posts))
+	for id, post := range copy.Posts {
+		posts[id] = post.ToSparseMap(fields)
+	}
+
+	b, err := json.Marshal(map[string]interface{}{"order": copy.Order, "posts": posts})
+	if err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
 func (o *PostList) MakeNonNil() {
 	if o.Order == nil {
 		o.Order = make([]string, 0)
@@ -94,10 +116,31 @@ func (o *PostList) Extend(other *PostList) {
 
 func (o *PostList) SortByCreateAt() {
 	sort.Slice(o.Order, func(i, j int) bool {
-		return o.Posts[o.Order[i]].CreateAt > o.Posts[o.Order[j]].CreateAt
+		a, b := o.Posts[o.Order[i]], o.Posts[o.Order[j]]
+		if a.CreateAt == b.CreateAt {
+			return a.Id > b.Id
+		}
+		return a.CreateAt > b.CreateAt
 	})
 }
 
+// RemovePostsCreatedAfter removes the posts that were made after the given time from the list.
+func (o *PostList) RemovePostsCreatedAfter(createAt int64) {
+	order := make([]string, 0, len(o.Order))
+	for _, postId := range o.Order {
+		if post, ok := o.Posts[postId]; ok && post.CreateAt <= createAt {
+			order = append(order, postId)
+		}
+	}
+	o.Order = order
+
+	for postId, post := range o.Posts {
+		if post.CreateAt > createAt {
+			delete(o.Posts, postId)
+		}
+	}
+}
+
 func (o *PostList) Etag() string {
 
 	id := "0"
@@ -37,6 +37,27 @@ func TestPostListJson(t *testing.T) {
 	}
 }
 
+func TestPostListToSparseJson(t *testing.T) {
+	pl := NewPostList()
+	p1 := &Post{Id: NewId(), UserId: NewId(), Message: NewId()}
+	p1.AddProp("attachments", []*SlackAttachment{
+		{Actions: []*PostAction{{Id: NewId(), Integration: &PostActionIntegration{URL: "http://localhost/secret"}}}},
+	})
+	pl.AddPost(p1)
+	pl.AddOrder(p1.Id)
+
+	json := pl.ToSparseJson([]string{"id", "message", "props"})
+	assert.NotContains(t, json, "user_id")
+	assert.NotContains(t, json, "secret", "action integrations should be stripped")
+
+	rpl := PostListFromJson(strings.NewReader(json))
+	assert.Equal(t, pl.Order, rpl.Order)
+	assert.Equal(t, p1.Message, rpl.Posts[p1.Id].Message)
+	assert.Empty(t, rpl.Posts[p1.Id].UserId)
+
+	assert.Equal(t, pl.ToJson(), pl.ToSparseJson(nil))
+}
+
 func TestPostListExtend(t *testing.T) {
 	l1 := PostList{}
 
@@ -90,3 +111,23 @@ func TestPostListSortByCreateAt(t *testing.T) {
 	assert.EqualValues(t, pl.Order[1], p1.Id)
 	assert.EqualValues(t, pl.Order[2], p2.Id)
 }
+
+func TestPostListRemovePostsCreatedAfter(t *testing.T) {
+	pl := PostList{}
+	p1 := &Post{Id: NewId(), Message: NewId(), CreateAt: 1}
+	pl.AddPost(p1)
+	p2 := &Post{Id: NewId(), Message: NewId(), CreateAt: 2}
+	pl.AddPost(p2)
+	p3 := &Post{Id: NewId(), Message: NewId(), CreateAt: 3}
+	pl.AddPost(p3)
+
+	pl.AddOrder(p3.Id)
+	pl.AddOrder(p2.Id)
+
+	pl.RemovePostsCreatedAfter(2)
+
+	assert.Equal(t, []string{p2.Id}, pl.Order)
+	assert.Len(t, pl.Posts, 2)
+	assert.Contains(t, pl.Posts, p1.Id, "posts that aren't in the order, such as roots of threads, should be kept too")
+	assert.NotContains(t, pl.Posts, p3.Id)
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	POST_EMBED_PERMALINK = "permalink"
+	POST_EMBED_IMAGE     = "image"
+	POST_EMBED_OPENGRAPH = "opengraph"
+
+	POST_PERMALINK_PREVIEW_MAX_RUNES = 300
+	POST_MAX_PERMALINK_PREVIEWS      = 5
+)
+
+// PostMetadata holds information about a post that depends on who it's being sent to, such as previews of the
+// posts that it links to.
+type PostMetadata struct {
+	Embeds []*PostEmbed `json:"embeds,omitempty"`
+
+	// Reactions summarizes the reactions to the post by emoji name. The full list of reactions is available
+	// from the reactions endpoint of the post.
+	Reactions map[string]*ReactionSummary `json:"reactions,omitempty"`
+
+	// Files and Emojis are only included for clients that ask for them. Emojis are the custom emojis used in the
+	// post's message and reactions.
+	Files  []*FileInfo `json:"files,omitempty"`
+	Emojis []*Emoji    `json:"emojis,omitempty"`
+}
+
+// ReactionSummary is the number of reactions with an emoji and whether the user that the post was sent to is
+// one of the users who reacted with it.
+type ReactionSummary struct {
+	Count int64 `json:"count"`
+	Me    bool  `json:"me"`
+}
+
+// PostEmbed is something that a post links to that can be shown along with it.
+type PostEmbed struct {
+	Type string      `json:"type"`
+	URL  string      `json:"url"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+// PermalinkPreview is the data of a permalink embed.
+type PermalinkPreview struct {
+	PostId             string `json:"post_id"`
+	UserId             string `json:"user_id"`
+	Username           string `json:"username"`
+	ChannelId          string `json:"channel_id"`
+	ChannelDisplayName string `json:"channel_display_name"`
+	CreateAt           int64  `json:"create_at"`
+	Message            string `json:"message"`
+	ThumbnailURL       string `json:"thumbnail_url,omitempty"`
+
+	// UserDeactivated is true if the user who made the post has been deactivated, so that it can be shown next to
+	// their username.
+	UserDeactivated bool `json:"user_deactivated,omitempty"`
+}
+
+// PostLink is a link found in the message of a post that isn't a permalink.
+type PostLink struct {
+	URL     string
+	IsImage bool
+}
+
+// PostPermalink is a link to a post found in the message of another post.
+type PostPermalink struct {
+	URL    string
+	PostId string
+}
+
+// FindPostPermalinks returns the distinct links to posts on siteURL in a message, up to
+// POST_MAX_PERMALINK_PREVIEWS of them.
+func FindPostPermalinks(message string, siteURL string) []*PostPermalink {
+	if siteURL == "" {
+		return nil
+	}
+
+	pattern := regexp.MustCompile(regexp.QuoteMeta(siteURL) + `/[a-z0-9\-_]+/pl/([a-z0-9]{26})\b`)
+
+	var permalinks []*PostPermalink
+	seen := make(map[string]bool)
+
+	for _, match := range pattern.FindAllStringSubmatch(message, -1) {
+		if seen[match[1]] {
+			continue
+		}
+		seen[match[1]] = true
+
+		permalinks = append(permalinks, &PostPermalink{URL: match[0], PostId: match[1]})
+		if len(permalinks) == POST_MAX_PERMALINK_PREVIEWS {
+			break
+		}
+	}
+
+	return permalinks
+}
+
+var postLinkPattern = regexp.MustCompile(`https?://[^\s<>()\[\]"'` + "`" + `]+`)
+
+// FindPostLink returns the first link in a message that doesn't go to siteURL, which is the one that gets an
+// image or OpenGraph embed, or nil if there isn't one.
+func FindPostLink(message string, siteURL string) *PostLink {
+	for _, match := range postLinkPattern.FindAllString(message, -1) {
+		link := strings.TrimRight(match, ".,;:!?")
+		if siteURL != "" && strings.HasPrefix(link, siteURL) {
+			continue
+		}
+
+		parsed, err := url.Parse(link)
+		if err != nil || parsed.Host == "" {
+			continue
+		}
+
+		return &PostLink{URL: link, IsImage: IsFileExtImage(path.Ext(parsed.Path))}
+	}
+
+	return nil
+}
+
+var postEmojiPattern = regexp.MustCompile(`:([a-zA-Z0-9_\-]+):`)
+
+// FindCustomEmojiNames returns the distinct names of the emojis used in a message that aren't system emojis.
+func FindCustomEmojiNames(message string) []string {
+	var names []string
+	seen := make(map[string]bool)
+
+	for _, match := range postEmojiPattern.FindAllStringSubmatch(message, -1) {
+		name := match[1]
+		if seen[name] || inSystemEmoji(name) {
+			continue
+		}
+		seen[name] = true
+
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// TruncateRunes returns s cut down to at most maxRunes runes, ending with an ellipsis if anything was
+// removed.
+func TruncateRunes(s string, maxRunes int) string {
+	runes := []rune(s)
+	if len(runes) <= maxRunes {
+		return s
+	}
+
+	return string(append(runes[:maxRunes-1], '…'))
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindPostPermalinks(t *testing.T) {
+	siteURL := "https://chat.example.com"
+	id1 := NewId()
+	id2 := NewId()
+
+	message := fmt.Sprintf("see %v/team-a/pl/%v and %v/team_b/pl/%v, also %v/team-a/pl/%v again", siteURL, id1, siteURL, id2, siteURL, id1)
+	assert.Equal(t, []*PostPermalink{
+		{URL: siteURL + "/team-a/pl/" + id1, PostId: id1},
+		{URL: siteURL + "/team_b/pl/" + id2, PostId: id2},
+	}, FindPostPermalinks(message, siteURL))
+
+	assert.Nil(t, FindPostPermalinks("https://elsewhere.example.com/team/pl/"+id1, siteURL))
+	assert.Nil(t, FindPostPermalinks(siteURL+"/team/pl/"+id1+"extra", siteURL))
+	assert.Nil(t, FindPostPermalinks(siteURL+"/team/channels/town-square", siteURL))
+	assert.Nil(t, FindPostPermalinks(siteURL+"/team/pl/"+id1, ""))
+
+	var many []string
+	for i := 0; i < POST_MAX_PERMALINK_PREVIEWS+2; i++ {
+		many = append(many, siteURL+"/team/pl/"+NewId())
+	}
+	assert.Len(t, FindPostPermalinks(strings.Join(many, " "), siteURL), POST_MAX_PERMALINK_PREVIEWS)
+}
+
+func TestFindPostLink(t *testing.T) {
+	siteURL := "https://chat.example.com"
+
+	assert.Equal(t, &PostLink{URL: "https://www.example.com/page"}, FindPostLink("see https://www.example.com/page.", siteURL))
+	assert.Equal(t, &PostLink{URL: "http://www.example.com/cat.PNG", IsImage: true}, FindPostLink("look (http://www.example.com/cat.PNG)", siteURL))
+	assert.Equal(t, &PostLink{URL: "https://www.example.com/b"}, FindPostLink(siteURL+"/team/pl/"+NewId()+" and https://www.example.com/b", siteURL))
+	assert.Equal(t, &PostLink{URL: "https://www.example.com/a"}, FindPostLink("https://www.example.com/a https://www.example.com/b.gif", siteURL))
+	assert.Nil(t, FindPostLink("no links here", siteURL))
+	assert.Nil(t, FindPostLink(siteURL+"/team/channels/town-square", siteURL))
+}
+
+func TestFindCustomEmojiNames(t *testing.T) {
+	assert.Equal(t, []string{"party_parrot", "my-emoji"}, FindCustomEmojiNames(":party_parrot: :smile: :my-emoji: :party_parrot:"))
+	assert.Nil(t, FindCustomEmojiNames(":smile: :+1: plain text"))
+}
+
+func TestTruncateRunes(t *testing.T) {
+	assert.Equal(t, "", TruncateRunes("", 3))
+	assert.Equal(t, "abc", TruncateRunes("abc", 3))
+	assert.Equal(t, "ab…", TruncateRunes("abcd", 3))
+	assert.Equal(t, "üü…", TruncateRunes("üüüü", 3))
+}
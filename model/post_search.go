@@ -0,0 +1,43 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+)
+
+const (
+	POST_SEARCH_DEFAULT_LIMIT = 100
+	POST_SEARCH_MAX_RESULTS   = 1000
+
+	POST_SEARCH_DEFAULT_PER_PAGE = 60
+	POST_SEARCH_MAX_PER_PAGE     = 200
+)
+
+// PostSearchResults is a page of the posts that match a search. The posts are ordered from newest to oldest,
+// with posts made at the same time ordered by id.
+type PostSearchResults struct {
+	*PostList
+
+	// TotalCount is how many posts match the search. If IsApproximate is set, there are more matches than
+	// were counted and TotalCount is a lower bound.
+	TotalCount    int64 `json:"total_count"`
+	IsApproximate bool  `json:"is_approximate"`
+
+	// SearchAt is when the search was made. Passing it back when getting the following pages leaves out the
+	// posts made in the meantime so that the pages stay stable.
+	SearchAt int64 `json:"search_at"`
+}
+
+func (o *PostSearchResults) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func PostSearchResultsFromJson(data io.Reader) *PostSearchResults {
+	var o *PostSearchResults
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
@@ -0,0 +1,36 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostSearchResultsJson(t *testing.T) {
+	post := &Post{Id: NewId(), Message: "hello"}
+
+	list := NewPostList()
+	list.AddPost(post)
+	list.AddOrder(post.Id)
+
+	results := &PostSearchResults{PostList: list, TotalCount: 12, IsApproximate: true, SearchAt: 1234}
+	json := results.ToJson()
+
+	decoded := PostSearchResultsFromJson(strings.NewReader(json))
+	require.NotNil(t, decoded)
+	require.NotNil(t, decoded.PostList)
+	assert.Equal(t, []string{post.Id}, decoded.Order)
+	assert.Equal(t, "hello", decoded.Posts[post.Id].Message)
+	assert.Equal(t, int64(12), decoded.TotalCount)
+	assert.True(t, decoded.IsApproximate)
+	assert.Equal(t, int64(1234), decoded.SearchAt)
+
+	// the results can still be read as a plain post list
+	asList := PostListFromJson(strings.NewReader(json))
+	assert.Equal(t, []string{post.Id}, asList.Order)
+}
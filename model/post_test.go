@@ -174,6 +174,89 @@ func TestPostSanitizeProps(t *testing.T) {
 	if post3.Props["attachments"] == nil {
 		t.Fatal("should not be nil")
 	}
+
+	post4 := &Post{
+		Message: "test",
+		Props: StringInterface{
+			POST_PROPS_FORWARDED_POST: map[string]interface{}{"post_id": NewId()},
+		},
+	}
+
+	post4.SanitizeProps()
+
+	assert.Nil(t, post4.Props[POST_PROPS_FORWARDED_POST], "only the server can mark a post as forwarded")
+
+	t.Run("unknown reserved props", func(t *testing.T) {
+		reservedPostProps["mm_known"] = true
+		defer delete(reservedPostProps, "mm_known")
+
+		post := &Post{
+			Message: "test",
+			Props: StringInterface{
+				"mm_unknown": "removed",
+				"mm_known":   "kept",
+				"mmkept":     "kept",
+				"other_mm_":  "kept",
+			},
+		}
+
+		post.SanitizeProps()
+
+		assert.Equal(t, StringInterface{
+			"mm_known":  "kept",
+			"mmkept":    "kept",
+			"other_mm_": "kept",
+		}, post.Props)
+	})
+}
+
+func TestPostPropsSize(t *testing.T) {
+	assert.Equal(t, len("null"), (&Post{}).PropsSize())
+	assert.Equal(t, len(`{"a":"ü"}`), (&Post{Props: StringInterface{"a": "ü"}}).PropsSize())
+}
+
+func TestPostIsEmpty(t *testing.T) {
+	assert.True(t, (&Post{}).IsEmpty())
+	assert.True(t, (&Post{Message: " \n\t"}).IsEmpty())
+	assert.True(t, (&Post{Props: StringInterface{"attachments": []interface{}{}}}).IsEmpty())
+
+	assert.False(t, (&Post{Message: "message"}).IsEmpty())
+	assert.False(t, (&Post{FileIds: StringArray{NewId()}}).IsEmpty())
+	assert.False(t, (&Post{Props: StringInterface{"attachments": []interface{}{map[string]interface{}{"text": "text"}}}}).IsEmpty())
+}
+
+func TestPostActionConfirmAndDisable(t *testing.T) {
+	o := &Post{
+		Props: StringInterface{
+			"attachments": []interface{}{
+				map[string]interface{}{
+					"actions": []interface{}{
+						map[string]interface{}{
+							"id":          "delete",
+							"name":        "Delete",
+							"integration": map[string]interface{}{"url": "http://example.com"},
+							"confirm":     map[string]interface{}{"title": "Delete?", "message": "This can't be undone."},
+						},
+						map[string]interface{}{"id": "keep", "name": "Keep"},
+					},
+				},
+			},
+		},
+	}
+
+	o.DisableActions([]string{"keep"})
+	assert.False(t, o.GetAction("delete").Disabled)
+	assert.True(t, o.GetAction("keep").Disabled)
+	assert.Equal(t, &PostActionConfirm{Title: "Delete?", Message: "This can't be undone."}, o.GetAction("delete").Confirm)
+
+	ro := PostFromJson(strings.NewReader(o.ToJson()))
+	action := ro.GetAction("delete")
+	assert.Nil(t, action.Integration, "integrations shouldn't be sent to clients")
+	assert.Equal(t, "Delete?", action.Confirm.Title, "confirmations should be sent to clients")
+	assert.True(t, ro.GetAction("keep").Disabled)
+
+	o.DisableActions([]string{POST_ACTION_ALL})
+	assert.True(t, o.GetAction("delete").Disabled)
 }
 
 var markdownSample, markdownSampleWithRewrittenImageURLs string
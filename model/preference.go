@@ -18,9 +18,21 @@ const (
 	PREFERENCE_CATEGORY_ADVANCED_SETTINGS   = "advanced_settings"
 	PREFERENCE_CATEGORY_FLAGGED_POST        = "flagged_post"
 	PREFERENCE_CATEGORY_FAVORITE_CHANNEL    = "favorite_channel"
+	PREFERENCE_CATEGORY_GROUP_CHANNEL_SHOW  = "group_channel_show"
+	PREFERENCE_CATEGORY_SIDEBAR_SETTINGS    = "sidebar_settings"
+
+	PREFERENCE_NAME_JOIN_LEAVE = "join_leave" // in the advanced settings, "false" hides join/leave messages
+
+	PREFERENCE_CATEGORY_CHANNEL_OPEN_TIME             = "channel_open_time"
+	PREFERENCE_CATEGORY_CHANNEL_APPROXIMATE_VIEW_TIME = "channel_approximate_view_time"
+	PREFERENCE_CATEGORY_AUTO_RESET_MANUAL_STATUS      = "auto_reset_manual_status"
 
 	PREFERENCE_CATEGORY_DISPLAY_SETTINGS = "display_settings"
 	PREFERENCE_NAME_COLLAPSE_SETTING     = "collapse_previews"
+	PREFERENCE_NAME_COLLAPSED_THREADS    = "collapsed_reply_threads"
+
+	COLLAPSED_THREADS_ON  = "on"
+	COLLAPSED_THREADS_OFF = "off"
 
 	PREFERENCE_CATEGORY_THEME = "theme"
 	// the name for theme props is the team id
@@ -32,13 +44,54 @@ const (
 	PREFERENCE_NAME_LAST_CHANNEL = "channel"
 	PREFERENCE_NAME_LAST_TEAM    = "team"
 
+	// saved searches are changed through their own endpoints, so the category isn't known to clients
+	PREFERENCE_CATEGORY_SAVED_SEARCH = "saved_search"
+
+	// blocked users are also changed through their own endpoints. The name is the id of the blocked user.
+	PREFERENCE_CATEGORY_BLOCKED_USER = "blocked_user"
+
+	// warnings that a user is about to be deactivated for being idle are only set by the server
+	PREFERENCE_CATEGORY_IDLE_DEACTIVATION = "idle_deactivation"
+	PREFERENCE_NAME_IDLE_WARNED_AT        = "warned_at"
+
 	PREFERENCE_CATEGORY_NOTIFICATIONS = "notifications"
 	PREFERENCE_NAME_EMAIL_INTERVAL    = "email_interval"
 
 	PREFERENCE_EMAIL_INTERVAL_NO_BATCHING_SECONDS = "30"  // the "immediate" setting is actually 30s
 	PREFERENCE_EMAIL_INTERVAL_BATCHING_SECONDS    = "900" // fifteen minutes is 900 seconds
+
+	PREFERENCES_MAX_UPDATE = 100
 )
 
+// knownPreferenceCategories are the categories clients may save preferences in. Categories mapped to true
+// are named after the id of the object they refer to, such as a channel, user, post or OAuth app.
+var knownPreferenceCategories = map[string]bool{
+	PREFERENCE_CATEGORY_DIRECT_CHANNEL_SHOW:           true,
+	PREFERENCE_CATEGORY_GROUP_CHANNEL_SHOW:            true,
+	PREFERENCE_CATEGORY_TUTORIAL_STEPS:                false,
+	PREFERENCE_CATEGORY_ADVANCED_SETTINGS:             false,
+	PREFERENCE_CATEGORY_FLAGGED_POST:                  true,
+	PREFERENCE_CATEGORY_FAVORITE_CHANNEL:              true,
+	PREFERENCE_CATEGORY_SIDEBAR_SETTINGS:              false,
+	PREFERENCE_CATEGORY_CHANNEL_OPEN_TIME:             true,
+	PREFERENCE_CATEGORY_CHANNEL_APPROXIMATE_VIEW_TIME: true,
+	PREFERENCE_CATEGORY_AUTO_RESET_MANUAL_STATUS:      false,
+	PREFERENCE_CATEGORY_DISPLAY_SETTINGS:              false,
+	PREFERENCE_CATEGORY_THEME:                         false,
+	PREFERENCE_CATEGORY_AUTHORIZED_OAUTH_APP:          true,
+	PREFERENCE_CATEGORY_LAST:                          false,
+	PREFERENCE_CATEGORY_NOTIFICATIONS:                 false,
+}
+
+// ChannelPreferenceCategories are the categories whose preferences are named after a channel id, and so
+// are meaningless once that channel no longer exists.
+var ChannelPreferenceCategories = []string{
+	PREFERENCE_CATEGORY_GROUP_CHANNEL_SHOW,
+	PREFERENCE_CATEGORY_FAVORITE_CHANNEL,
+	PREFERENCE_CATEGORY_CHANNEL_OPEN_TIME,
+	PREFERENCE_CATEGORY_CHANNEL_APPROXIMATE_VIEW_TIME,
+}
+
 type Preference struct {
 	UserId   string `json:"user_id"`
 	Category string `json:"category"`
@@ -84,6 +137,30 @@ func (o *Preference) IsValid() *AppError {
 	return nil
 }
 
+// IsValidForClient checks, in addition to IsValid, that the preference is in a category known to the server
+// and that its name is an id for the categories that are named after one.
+func (o *Preference) IsValidForClient() *AppError {
+	if err := o.IsValid(); err != nil {
+		return err
+	}
+
+	namedById, known := knownPreferenceCategories[o.Category]
+	if !known {
+		return NewAppError("Preference.IsValidForClient", "model.preference.is_valid.unknown_category.app_error", nil, "category="+o.Category, http.StatusBadRequest)
+	}
+
+	if namedById && !IsValidId(o.Name) {
+		return NewAppError("Preference.IsValidForClient", "model.preference.is_valid.name.app_error", nil, "category="+o.Category+", name="+o.Name, http.StatusBadRequest)
+	}
+
+	// the theme for all teams is saved without a name
+	if o.Category == PREFERENCE_CATEGORY_THEME && len(o.Name) > 0 && !IsValidId(o.Name) {
+		return NewAppError("Preference.IsValidForClient", "model.preference.is_valid.name.app_error", nil, "category="+o.Category+", name="+o.Name, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
 func (o *Preference) PreUpdate() {
 	if o.Category == PREFERENCE_CATEGORY_THEME {
 		// decode the value of theme (a map of strings to string) and eliminate any invalid values
@@ -87,3 +87,42 @@ func TestPreferencePreUpdate(t *testing.T) {
 		t.Fatal("should have changed invalid prop")
 	}
 }
+
+func TestPreferenceIsValidForClient(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Category string
+		Name     string
+		Valid    bool
+	}{
+		"known category":              {PREFERENCE_CATEGORY_DISPLAY_SETTINGS, PREFERENCE_NAME_COLLAPSE_SETTING, true},
+		"unknown category":            {NewId(), NewId(), false},
+		"named by id":                 {PREFERENCE_CATEGORY_FAVORITE_CHANNEL, NewId(), true},
+		"named by id with other name": {PREFERENCE_CATEGORY_FAVORITE_CHANNEL, "junk", false},
+		"theme for all teams":         {PREFERENCE_CATEGORY_THEME, "", true},
+		"theme for a team":            {PREFERENCE_CATEGORY_THEME, NewId(), true},
+		"theme with other name":       {PREFERENCE_CATEGORY_THEME, "junk", false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			preference := Preference{
+				UserId:   NewId(),
+				Category: tc.Category,
+				Name:     tc.Name,
+			}
+			if tc.Category == PREFERENCE_CATEGORY_THEME {
+				preference.Value = "{}"
+			}
+
+			err := preference.IsValidForClient()
+			if tc.Valid && err != nil {
+				t.Fatal(err)
+			} else if !tc.Valid && err == nil {
+				t.Fatal("should be invalid")
+			}
+		})
+	}
+
+	preference := Preference{UserId: "garbage", Category: PREFERENCE_CATEGORY_DISPLAY_SETTINGS}
+	if err := preference.IsValidForClient(); err == nil {
+		t.Fatal("should also check IsValid")
+	}
+}
@@ -25,3 +25,35 @@ func PreferencesFromJson(data io.Reader) (Preferences, error) {
 		return nil, err
 	}
 }
+
+// SplitByJsonSize splits the preferences, in order, into groups whose JSON is at most maxSize bytes. A preference
+// that's larger than that by itself is put in a group of its own.
+func (o Preferences) SplitByJsonSize(maxSize int) []Preferences {
+	var groups []Preferences
+
+	var group Preferences
+	groupSize := 2 // The brackets around the array
+	for _, preference := range o {
+		b, _ := json.Marshal(preference)
+		size := len(b)
+		if len(group) > 0 {
+			size++ // The comma before it
+		}
+
+		if len(group) > 0 && groupSize+size > maxSize {
+			groups = append(groups, group)
+			group = nil
+			groupSize = 2
+			size = len(b)
+		}
+
+		group = append(group, preference)
+		groupSize += size
+	}
+
+	if len(group) > 0 {
+		groups = append(groups, group)
+	}
+
+	return groups
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreferencesSplitByJsonSize(t *testing.T) {
+	var preferences Preferences
+	for i := 0; i < 10; i++ {
+		preferences = append(preferences, Preference{UserId: NewId(), Category: PREFERENCE_CATEGORY_FLAGGED_POST, Name: NewId(), Value: "true"})
+	}
+	first := preferences[:1]
+	size := len(first.ToJson())
+
+	t.Run("fits", func(t *testing.T) {
+		groups := preferences.SplitByJsonSize(len(preferences.ToJson()))
+		assert.Equal(t, []Preferences{preferences}, groups)
+	})
+
+	t.Run("split", func(t *testing.T) {
+		// Three preferences fit in each group along with the commas between them
+		groups := preferences.SplitByJsonSize(3*size - 2)
+		assert.Equal(t, []Preferences{preferences[0:3], preferences[3:6], preferences[6:9], preferences[9:]}, groups)
+		for _, group := range groups {
+			assert.True(t, len(group.ToJson()) <= 3*size-2)
+		}
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		large := Preferences{{UserId: NewId(), Category: PREFERENCE_CATEGORY_DISPLAY_SETTINGS, Name: "large", Value: strings.Repeat("a", 100)}, preferences[0]}
+		groups := large.SplitByJsonSize(size)
+		assert.Equal(t, []Preferences{large[:1], large[1:]}, groups)
+	})
+
+	t.Run("none", func(t *testing.T) {
+		assert.Empty(t, Preferences{}.SplitByJsonSize(size))
+	})
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	PREFLIGHT_STATUS_PASS = "PASS"
+	PREFLIGHT_STATUS_WARN = "WARN"
+	PREFLIGHT_STATUS_FAIL = "FAIL"
+)
+
+// PreflightCheck is the result of one of the checks made on the config and the services it points to before the
+// server starts.
+type PreflightCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+func (o *PreflightCheck) String() string {
+	return fmt.Sprintf("%v %v: %v", o.Status, o.Name, o.Message)
+}
+
+// PreflightReport is the result of every check made before the server starts. Checks that can't be passed without a
+// change to the config or the environment fail, and the others only warn.
+type PreflightReport struct {
+	Checks []*PreflightCheck `json:"checks"`
+}
+
+func (o *PreflightReport) Add(name string, status string, message string) {
+	o.Checks = append(o.Checks, &PreflightCheck{Name: name, Status: status, Message: message})
+}
+
+// Failures returns the checks that failed.
+func (o *PreflightReport) Failures() []*PreflightCheck {
+	var failures []*PreflightCheck
+	for _, check := range o.Checks {
+		if check.Status == PREFLIGHT_STATUS_FAIL {
+			failures = append(failures, check)
+		}
+	}
+	return failures
+}
+
+func (o *PreflightReport) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
@@ -0,0 +1,24 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreflightReport(t *testing.T) {
+	report := &PreflightReport{}
+	report.Add("Database", PREFLIGHT_STATUS_PASS, "Connected")
+	report.Add("SMTP", PREFLIGHT_STATUS_WARN, "Unable to connect")
+	assert.Len(t, report.Failures(), 0)
+
+	report.Add("Listen address", PREFLIGHT_STATUS_FAIL, "Port in use")
+	failures := report.Failures()
+	assert.Len(t, failures, 1)
+	assert.Equal(t, "FAIL Listen address: Port in use", failures[0].String())
+
+	assert.Contains(t, report.ToJson(), `"status":"WARN"`)
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+const (
+	PROVISIONING_TOKEN_DESCRIPTION_MAX_LENGTH = 255
+)
+
+// ProvisioningToken lets an identity provider manage users and groups through the SCIM endpoints. Unlike a user
+// access token, it doesn't act as any user, so it can only be used for provisioning.
+type ProvisioningToken struct {
+	Id          string `json:"id"`
+	Token       string `json:"token,omitempty"`
+	CreatorId   string `json:"creator_id"`
+	CreateAt    int64  `json:"create_at"`
+	Description string `json:"description"`
+}
+
+func (t *ProvisioningToken) IsValid() *AppError {
+	if len(t.Id) != 26 {
+		return NewAppError("ProvisioningToken.IsValid", "model.provisioning_token.is_valid.id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if len(t.Token) != 26 {
+		return NewAppError("ProvisioningToken.IsValid", "model.provisioning_token.is_valid.token.app_error", nil, "id="+t.Id, http.StatusBadRequest)
+	}
+
+	if len(t.CreatorId) != 26 {
+		return NewAppError("ProvisioningToken.IsValid", "model.provisioning_token.is_valid.creator_id.app_error", nil, "id="+t.Id, http.StatusBadRequest)
+	}
+
+	if t.CreateAt == 0 {
+		return NewAppError("ProvisioningToken.IsValid", "model.provisioning_token.is_valid.create_at.app_error", nil, "id="+t.Id, http.StatusBadRequest)
+	}
+
+	if len(t.Description) > PROVISIONING_TOKEN_DESCRIPTION_MAX_LENGTH {
+		return NewAppError("ProvisioningToken.IsValid", "model.provisioning_token.is_valid.description.app_error", nil, "id="+t.Id, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (t *ProvisioningToken) PreSave() {
+	t.Id = NewId()
+	t.Token = NewId()
+	t.CreateAt = GetMillis()
+}
+
+// Sanitize removes the secret so that the token can be listed without being usable.
+func (t *ProvisioningToken) Sanitize() {
+	t.Token = ""
+}
+
+func (t *ProvisioningToken) ToJson() string {
+	b, _ := json.Marshal(t)
+	return string(b)
+}
+
+func ProvisioningTokenFromJson(data io.Reader) *ProvisioningToken {
+	var t *ProvisioningToken
+	json.NewDecoder(data).Decode(&t)
+	return t
+}
+
+func ProvisioningTokenListToJson(t []*ProvisioningToken) string {
+	b, _ := json.Marshal(t)
+	return string(b)
+}
+
+func ProvisioningTokenListFromJson(data io.Reader) []*ProvisioningToken {
+	var t []*ProvisioningToken
+	json.NewDecoder(data).Decode(&t)
+	return t
+}
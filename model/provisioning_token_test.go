@@ -0,0 +1,53 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProvisioningTokenJson(t *testing.T) {
+	token := ProvisioningToken{CreatorId: NewId(), Description: "okta"}
+	token.PreSave()
+
+	rtoken := ProvisioningTokenFromJson(strings.NewReader(token.ToJson()))
+	assert.Equal(t, token, *rtoken)
+
+	tokens := ProvisioningTokenListFromJson(strings.NewReader(ProvisioningTokenListToJson([]*ProvisioningToken{&token})))
+	if assert.Len(t, tokens, 1) {
+		assert.Equal(t, token, *tokens[0])
+	}
+
+	token.Sanitize()
+	assert.NotContains(t, token.ToJson(), "\"token\"")
+}
+
+func TestProvisioningTokenIsValid(t *testing.T) {
+	token := ProvisioningToken{}
+
+	err := token.IsValid()
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "model.provisioning_token.is_valid.id.app_error", err.Id)
+	}
+
+	token.CreatorId = NewId()
+	token.PreSave()
+	assert.Nil(t, token.IsValid())
+
+	token.Description = strings.Repeat("a", PROVISIONING_TOKEN_DESCRIPTION_MAX_LENGTH+1)
+	err = token.IsValid()
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "model.provisioning_token.is_valid.description.app_error", err.Id)
+	}
+
+	token.Description = ""
+	token.CreatorId = ""
+	err = token.IsValid()
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "model.provisioning_token.is_valid.creator_id.app_error", err.Id)
+	}
+}
@@ -44,6 +44,8 @@ type PushNotification struct {
 	OverrideUsername string `json:"override_username"`
 	OverrideIconUrl  string `json:"override_icon_url"`
 	FromWebhook      string `json:"from_webhook"`
+	// Permalink is a short link to the post, which is only sent when short permalinks are enabled.
+	Permalink string `json:"permalink,omitempty"`
 }
 
 func (me *PushNotification) ToJson() string {
@@ -17,6 +17,16 @@ type Reaction struct {
 	CreateAt  int64  `json:"create_at"`
 }
 
+// ReactionCount is the number of reactions with one emoji on a post.
+type ReactionCount struct {
+	PostId    string `json:"post_id"`
+	EmojiName string `json:"emoji_name"`
+	Count     int64  `json:"count"`
+
+	// Me is whether the user that the counts were fetched for is one of the users who reacted with the emoji.
+	Me bool `json:"me"`
+}
+
 func (o *Reaction) ToJson() string {
 	b, _ := json.Marshal(o)
 	return string(b)
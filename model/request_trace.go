@@ -0,0 +1,72 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+)
+
+const (
+	REQUEST_TRACE_SPAN_STORE       = "store"
+	REQUEST_TRACE_SPAN_FILESTORE   = "filestore"
+	REQUEST_TRACE_SPAN_PLUGIN_HOOK = "plugin_hook"
+
+	// REQUEST_TRACE_MAX_SPANS limits how many spans are kept for a trace so that a request that loops over the store
+	// doesn't use up lots of memory. Any more are counted in DroppedSpans.
+	REQUEST_TRACE_MAX_SPANS = 500
+)
+
+// RequestTrace is how long an API request took to handle, broken down into the time spent in the store, the file
+// store and plugin hooks along the way.
+type RequestTrace struct {
+	RequestId      string              `json:"request_id"`
+	Handler        string              `json:"handler"`
+	Method         string              `json:"method"`
+	Path           string              `json:"path"`
+	UserId         string              `json:"user_id"`
+	ErrorId        string              `json:"error_id,omitempty"`
+	StartAt        int64               `json:"start_at"`
+	DurationMillis float64             `json:"duration_millis"`
+	Spans          []*RequestTraceSpan `json:"spans"`
+	DroppedSpans   int                 `json:"dropped_spans"`
+}
+
+// RequestTraceSpan is a call made while handling a request. StartMillis is how long after the request started that
+// the call was made.
+type RequestTraceSpan struct {
+	Kind           string  `json:"kind"`
+	Name           string  `json:"name"`
+	StartMillis    float64 `json:"start_millis"`
+	DurationMillis float64 `json:"duration_millis"`
+}
+
+// SpanTotals returns how many calls of each kind were made while handling the request and how long they took
+// altogether. Calls that run at the same time are each counted in full.
+func (t *RequestTrace) SpanTotals() (map[string]int, map[string]float64) {
+	counts := map[string]int{}
+	durations := map[string]float64{}
+	for _, span := range t.Spans {
+		counts[span.Kind]++
+		durations[span.Kind] += span.DurationMillis
+	}
+
+	return counts, durations
+}
+
+func (t *RequestTrace) ToJson() string {
+	b, _ := json.Marshal(t)
+	return string(b)
+}
+
+func RequestTraceListToJson(traces []*RequestTrace) string {
+	b, _ := json.Marshal(traces)
+	return string(b)
+}
+
+func RequestTraceListFromJson(data io.Reader) []*RequestTrace {
+	var traces []*RequestTrace
+	json.NewDecoder(data).Decode(&traces)
+	return traces
+}
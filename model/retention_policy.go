@@ -0,0 +1,96 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"net/http"
+	"unicode/utf8"
+)
+
+const (
+	RETENTION_POLICY_DISPLAY_NAME_MAX_RUNES = 64
+)
+
+// RetentionPolicy overrides the global basic retention periods for the channels and teams assigned to it.
+// A retention period of 0 days keeps messages or files forever. Channels assigned directly to a policy
+// take precedence over the policy of their team.
+type RetentionPolicy struct {
+	Id                   string   `json:"id"`
+	CreateAt             int64    `json:"create_at"`
+	UpdateAt             int64    `json:"update_at"`
+	DisplayName          string   `json:"display_name"`
+	MessageRetentionDays int      `json:"message_retention_days"`
+	FileRetentionDays    int      `json:"file_retention_days"`
+	ChannelIds           []string `json:"channel_ids" db:"-"`
+	TeamIds              []string `json:"team_ids" db:"-"`
+}
+
+type RetentionPolicyChannel struct {
+	PolicyId  string `json:"policy_id"`
+	ChannelId string `json:"channel_id"`
+}
+
+type RetentionPolicyTeam struct {
+	PolicyId string `json:"policy_id"`
+	TeamId   string `json:"team_id"`
+}
+
+// RetentionRunResult reports how many posts and files a basic retention run deleted, or would have
+// deleted for a dry run.
+type RetentionRunResult struct {
+	DryRun       bool  `json:"dry_run"`
+	PostsDeleted int64 `json:"posts_deleted"`
+	FilesDeleted int64 `json:"files_deleted"`
+}
+
+func (o *RetentionPolicy) IsValid() *AppError {
+	if len(o.Id) != 26 {
+		return NewAppError("RetentionPolicy.IsValid", "model.retention_policy.is_valid.id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if o.CreateAt == 0 {
+		return NewAppError("RetentionPolicy.IsValid", "model.retention_policy.is_valid.create_at.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	if o.UpdateAt == 0 {
+		return NewAppError("RetentionPolicy.IsValid", "model.retention_policy.is_valid.update_at.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	if o.DisplayName == "" || utf8.RuneCountInString(o.DisplayName) > RETENTION_POLICY_DISPLAY_NAME_MAX_RUNES {
+		return NewAppError("RetentionPolicy.IsValid", "model.retention_policy.is_valid.display_name.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	if o.MessageRetentionDays < 0 {
+		return NewAppError("RetentionPolicy.IsValid", "model.retention_policy.is_valid.message_retention_days.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	if o.FileRetentionDays < 0 {
+		return NewAppError("RetentionPolicy.IsValid", "model.retention_policy.is_valid.file_retention_days.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (o *RetentionPolicy) PreSave() {
+	if o.Id == "" {
+		o.Id = NewId()
+	}
+
+	o.CreateAt = GetMillis()
+	o.UpdateAt = o.CreateAt
+}
+
+func (o *RetentionPolicy) PreUpdate() {
+	o.UpdateAt = GetMillis()
+}
+
+// RetentionCutoff returns the time in milliseconds before which content is deleted for a retention
+// period, or 0 if content is kept forever.
+func RetentionCutoff(now int64, retentionDays int) int64 {
+	if retentionDays <= 0 {
+		return 0
+	}
+
+	return now - int64(retentionDays)*24*60*60*1000
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetentionPolicyIsValid(t *testing.T) {
+	policy := &RetentionPolicy{
+		DisplayName:          "Short lived",
+		MessageRetentionDays: 30,
+	}
+	assert.NotNil(t, policy.IsValid())
+
+	policy.PreSave()
+	assert.Nil(t, policy.IsValid())
+
+	policy.DisplayName = ""
+	assert.NotNil(t, policy.IsValid())
+
+	policy.DisplayName = strings.Repeat("a", RETENTION_POLICY_DISPLAY_NAME_MAX_RUNES+1)
+	assert.NotNil(t, policy.IsValid())
+
+	policy.DisplayName = "Short lived"
+	policy.MessageRetentionDays = -1
+	assert.NotNil(t, policy.IsValid())
+
+	policy.MessageRetentionDays = 0
+	policy.FileRetentionDays = -1
+	assert.NotNil(t, policy.IsValid())
+}
+
+func TestRetentionCutoff(t *testing.T) {
+	now := int64(10 * 24 * 60 * 60 * 1000)
+
+	assert.Equal(t, int64(0), RetentionCutoff(now, 0))
+	assert.Equal(t, int64(0), RetentionCutoff(now, -1))
+	assert.Equal(t, now-24*60*60*1000, RetentionCutoff(now, 1))
+}
@@ -15,14 +15,19 @@ const (
 	SYSTEM_POST_ALL_ROLE_ID          = "system_post_all"
 	SYSTEM_POST_ALL_PUBLIC_ROLE_ID   = "system_post_all_public"
 	SYSTEM_USER_ACCESS_TOKEN_ROLE_ID = "system_user_access_token"
+	SYSTEM_GUEST_ROLE_ID             = "system_guest"
+	SYSTEM_READ_ONLY_ADMIN_ROLE_ID   = "system_read_only_admin"
+	SYSTEM_USER_MANAGER_ROLE_ID      = "system_user_manager"
 
 	TEAM_USER_ROLE_ID            = "team_user"
 	TEAM_ADMIN_ROLE_ID           = "team_admin"
 	TEAM_POST_ALL_ROLE_ID        = "team_post_all"
 	TEAM_POST_ALL_PUBLIC_ROLE_ID = "team_post_all_public"
+	TEAM_GUEST_ROLE_ID           = "team_guest"
 
 	CHANNEL_USER_ROLE_ID  = "channel_user"
 	CHANNEL_ADMIN_ROLE_ID = "channel_admin"
+	CHANNEL_GUEST_ROLE_ID = "channel_guest"
 
 	ROLE_NAME_MAX_LENGTH         = 64
 	ROLE_DISPLAY_NAME_MAX_LENGTH = 128
@@ -195,6 +200,22 @@ func MakeDefaultRoles() map[string]*Role {
 		Description: "authentication.roles.channel_admin.description",
 		Permissions: []string{
 			PERMISSION_MANAGE_CHANNEL_ROLES.Id,
+			PERMISSION_REMOVE_OTHERS_REACTIONS.Id,
+		},
+		SchemeManaged: true,
+	}
+
+	roles[CHANNEL_GUEST_ROLE_ID] = &Role{
+		Name:        "channel_guest",
+		DisplayName: "authentication.roles.channel_guest.name",
+		Description: "authentication.roles.channel_guest.description",
+		Permissions: []string{
+			PERMISSION_READ_CHANNEL.Id,
+			PERMISSION_ADD_REACTION.Id,
+			PERMISSION_REMOVE_REACTION.Id,
+			PERMISSION_UPLOAD_FILE.Id,
+			PERMISSION_CREATE_POST.Id,
+			PERMISSION_USE_SLASH_COMMANDS.Id,
 		},
 		SchemeManaged: true,
 	}
@@ -212,6 +233,16 @@ func MakeDefaultRoles() map[string]*Role {
 		SchemeManaged: true,
 	}
 
+	roles[TEAM_GUEST_ROLE_ID] = &Role{
+		Name:        "team_guest",
+		DisplayName: "authentication.roles.team_guest.name",
+		Description: "authentication.roles.team_guest.description",
+		Permissions: []string{
+			PERMISSION_VIEW_TEAM.Id,
+		},
+		SchemeManaged: true,
+	}
+
 	roles[TEAM_POST_ALL_ROLE_ID] = &Role{
 		Name:        "team_post_all",
 		DisplayName: "authentication.roles.team_post_all.name",
@@ -247,6 +278,8 @@ func MakeDefaultRoles() map[string]*Role {
 			PERMISSION_MANAGE_SLASH_COMMANDS.Id,
 			PERMISSION_MANAGE_OTHERS_SLASH_COMMANDS.Id,
 			PERMISSION_MANAGE_WEBHOOKS.Id,
+			PERMISSION_INVITE_GUEST.Id,
+			PERMISSION_REMOVE_OTHERS_REACTIONS.Id,
 		},
 		SchemeManaged: true,
 	}
@@ -259,6 +292,18 @@ func MakeDefaultRoles() map[string]*Role {
 			PERMISSION_CREATE_DIRECT_CHANNEL.Id,
 			PERMISSION_CREATE_GROUP_CHANNEL.Id,
 			PERMISSION_PERMANENT_DELETE_USER.Id,
+			PERMISSION_MANAGE_CUSTOM_GROUPS.Id,
+		},
+		SchemeManaged: true,
+	}
+
+	roles[SYSTEM_GUEST_ROLE_ID] = &Role{
+		Name:        "system_guest",
+		DisplayName: "authentication.roles.global_guest.name",
+		Description: "authentication.roles.global_guest.description",
+		Permissions: []string{
+			PERMISSION_CREATE_DIRECT_CHANNEL.Id,
+			PERMISSION_CREATE_GROUP_CHANNEL.Id,
 		},
 		SchemeManaged: true,
 	}
@@ -295,6 +340,35 @@ func MakeDefaultRoles() map[string]*Role {
 		SchemeManaged: true,
 	}
 
+	roles[SYSTEM_READ_ONLY_ADMIN_ROLE_ID] = &Role{
+		Name:        "system_read_only_admin",
+		DisplayName: "authentication.roles.system_read_only_admin.name",
+		Description: "authentication.roles.system_read_only_admin.description",
+		// Read only admins can see everything in the System Console, with the secrets in the config hidden,
+		// but can't change any of it
+		Permissions: []string{
+			PERMISSION_READ_SYSTEM_CONSOLE.Id,
+			PERMISSION_LIST_USERS_WITHOUT_TEAM.Id,
+		},
+		SchemeManaged: true,
+	}
+
+	roles[SYSTEM_USER_MANAGER_ROLE_ID] = &Role{
+		Name:        "system_user_manager",
+		DisplayName: "authentication.roles.system_user_manager.name",
+		Description: "authentication.roles.system_user_manager.description",
+		// User managers can create, edit and deactivate users other than admins, and manage which teams they're
+		// on, but have no access to the config or to anything that users have posted or set for themselves
+		Permissions: []string{
+			PERMISSION_MANAGE_USERS.Id,
+			PERMISSION_LIST_USERS_WITHOUT_TEAM.Id,
+			PERMISSION_VIEW_TEAM.Id,
+			PERMISSION_ADD_USER_TO_TEAM.Id,
+			PERMISSION_REMOVE_USER_FROM_TEAM.Id,
+		},
+		SchemeManaged: true,
+	}
+
 	roles[SYSTEM_ADMIN_ROLE_ID] = &Role{
 		Name:        "system_admin",
 		DisplayName: "authentication.roles.global_admin.name",
@@ -334,7 +408,12 @@ func MakeDefaultRoles() map[string]*Role {
 							PERMISSION_CREATE_USER_ACCESS_TOKEN.Id,
 							PERMISSION_READ_USER_ACCESS_TOKEN.Id,
 							PERMISSION_REVOKE_USER_ACCESS_TOKEN.Id,
-							PERMISSION_REMOVE_OTHERS_REACTIONS.Id,
+							PERMISSION_MANAGE_CUSTOM_GROUPS.Id,
+							PERMISSION_PROMOTE_GUEST.Id,
+							PERMISSION_DEMOTE_TO_GUEST.Id,
+							PERMISSION_IMPERSONATE_USER.Id,
+							PERMISSION_READ_SYSTEM_CONSOLE.Id,
+							PERMISSION_MANAGE_USERS.Id,
 						},
 						roles[TEAM_USER_ROLE_ID].Permissions...,
 					),
@@ -0,0 +1,111 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	SAVED_SEARCH_NAME_MAX_RUNES  = 64
+	SAVED_SEARCH_TERMS_MAX_RUNES = 500
+	SAVED_SEARCH_MAX_PER_USER    = 50
+)
+
+// SavedSearch is a search which a user has named so that clients can show it as a shortcut. Saved searches are
+// stored as preferences in PREFERENCE_CATEGORY_SAVED_SEARCH, named after their id.
+type SavedSearch struct {
+	Id         string `json:"id"`
+	Name       string `json:"name"`
+	Terms      string `json:"terms"`
+	IsOrSearch bool   `json:"is_or_search"`
+	CreateAt   int64  `json:"create_at"`
+}
+
+func (o *SavedSearch) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func SavedSearchFromJson(data io.Reader) *SavedSearch {
+	var o *SavedSearch
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
+
+func SavedSearchListToJson(l []*SavedSearch) string {
+	b, _ := json.Marshal(l)
+	return string(b)
+}
+
+func SavedSearchListFromJson(data io.Reader) []*SavedSearch {
+	var o []*SavedSearch
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
+
+func (o *SavedSearch) PreSave() {
+	if o.Id == "" {
+		o.Id = NewId()
+	}
+
+	o.Name = strings.TrimSpace(o.Name)
+	o.Terms = strings.TrimSpace(o.Terms)
+
+	if o.CreateAt == 0 {
+		o.CreateAt = GetMillis()
+	}
+}
+
+func (o *SavedSearch) IsValid() *AppError {
+	if !IsValidId(o.Id) {
+		return NewAppError("SavedSearch.IsValid", "model.saved_search.is_valid.id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if o.Name == "" || utf8.RuneCountInString(o.Name) > SAVED_SEARCH_NAME_MAX_RUNES {
+		return NewAppError("SavedSearch.IsValid", "model.saved_search.is_valid.name.app_error", map[string]interface{}{"Max": SAVED_SEARCH_NAME_MAX_RUNES}, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	if o.Terms == "" || utf8.RuneCountInString(o.Terms) > SAVED_SEARCH_TERMS_MAX_RUNES {
+		return NewAppError("SavedSearch.IsValid", "model.saved_search.is_valid.terms.app_error", map[string]interface{}{"Max": SAVED_SEARCH_TERMS_MAX_RUNES}, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	if o.CreateAt == 0 {
+		return NewAppError("SavedSearch.IsValid", "model.saved_search.is_valid.create_at.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+// ToPreference returns the preference that the saved search is stored as for userId.
+func (o *SavedSearch) ToPreference(userId string) *Preference {
+	value, _ := json.Marshal(o)
+
+	return &Preference{
+		UserId:   userId,
+		Category: PREFERENCE_CATEGORY_SAVED_SEARCH,
+		Name:     o.Id,
+		Value:    string(value),
+	}
+}
+
+// SavedSearchFromPreference returns the saved search that a preference stores, or nil if it doesn't hold one.
+func SavedSearchFromPreference(preference *Preference) *SavedSearch {
+	if preference.Category != PREFERENCE_CATEGORY_SAVED_SEARCH {
+		return nil
+	}
+
+	var o *SavedSearch
+	if err := json.Unmarshal([]byte(preference.Value), &o); err != nil || o == nil {
+		return nil
+	}
+
+	o.Id = preference.Name
+
+	return o
+}
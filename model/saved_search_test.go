@@ -0,0 +1,55 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSavedSearchIsValid(t *testing.T) {
+	search := &SavedSearch{Name: " standup ", Terms: " from:alice in:town-square "}
+	search.PreSave()
+
+	assert.Len(t, search.Id, 26)
+	assert.NotZero(t, search.CreateAt)
+	assert.Equal(t, "standup", search.Name)
+	assert.Equal(t, "from:alice in:town-square", search.Terms)
+	require.Nil(t, search.IsValid())
+
+	search.Name = ""
+	assert.NotNil(t, search.IsValid())
+
+	search.Name = strings.Repeat("a", SAVED_SEARCH_NAME_MAX_RUNES+1)
+	assert.NotNil(t, search.IsValid())
+
+	search.Name = strings.Repeat("ü", SAVED_SEARCH_NAME_MAX_RUNES)
+	assert.Nil(t, search.IsValid())
+
+	search.Terms = ""
+	assert.NotNil(t, search.IsValid())
+
+	search.Terms = strings.Repeat("a", SAVED_SEARCH_TERMS_MAX_RUNES+1)
+	assert.NotNil(t, search.IsValid())
+}
+
+func TestSavedSearchPreference(t *testing.T) {
+	search := &SavedSearch{Name: "standup", Terms: "#standup", IsOrSearch: true}
+	search.PreSave()
+
+	preference := search.ToPreference(NewId())
+	assert.Equal(t, PREFERENCE_CATEGORY_SAVED_SEARCH, preference.Category)
+	assert.Equal(t, search.Id, preference.Name)
+	require.Nil(t, preference.IsValid())
+
+	assert.Equal(t, search, SavedSearchFromPreference(preference))
+
+	preference.Value = "not json"
+	assert.Nil(t, SavedSearchFromPreference(preference))
+
+	assert.Nil(t, SavedSearchFromPreference(&Preference{Category: PREFERENCE_CATEGORY_THEME, Value: "{}"}))
+}
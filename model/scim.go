@@ -0,0 +1,537 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SCIM_SCHEMA_USER                    = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SCIM_SCHEMA_GROUP                   = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SCIM_SCHEMA_SERVICE_PROVIDER_CONFIG = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SCIM_SCHEMA_LIST_RESPONSE           = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SCIM_SCHEMA_PATCH_OP                = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SCIM_SCHEMA_ERROR                   = "urn:ietf:params:scim:api:messages:2.0:Error"
+
+	SCIM_RESOURCE_TYPE_USER  = "User"
+	SCIM_RESOURCE_TYPE_GROUP = "Group"
+
+	SCIM_URL_SUFFIX   = "/scim/v2"
+	SCIM_CONTENT_TYPE = "application/scim+json"
+
+	SCIM_DEFAULT_COUNT = 100
+	SCIM_MAX_COUNT     = 200
+
+	SCIM_EMAIL_TYPE_WORK = "work"
+
+	SCIM_PATCH_OP_ADD     = "add"
+	SCIM_PATCH_OP_REPLACE = "replace"
+	SCIM_PATCH_OP_REMOVE  = "remove"
+
+	SCIM_ERROR_TYPE_INVALID_FILTER = "invalidFilter"
+	SCIM_ERROR_TYPE_INVALID_PATH   = "invalidPath"
+	SCIM_ERROR_TYPE_INVALID_SYNTAX = "invalidSyntax"
+	SCIM_ERROR_TYPE_INVALID_VALUE  = "invalidValue"
+	SCIM_ERROR_TYPE_NO_TARGET      = "noTarget"
+	SCIM_ERROR_TYPE_UNIQUENESS     = "uniqueness"
+)
+
+var scimFilterPattern = regexp.MustCompile(`^\s*([A-Za-z][A-Za-z0-9._$-]*)\s+(?i:eq)\s+("(?:[^"\\]|\\.)*")\s*$`)
+var scimPathPattern = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9_$-]*)(?:\[(.+)\])?(?:\.([A-Za-z][A-Za-z0-9_$-]*))?$`)
+
+type ScimMeta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Location     string `json:"location,omitempty"`
+	Version      string `json:"version,omitempty"`
+}
+
+// NewScimMeta returns the metadata for a resource. Its version changes whenever the resource is updated, so it's
+// also used as the resource's ETag.
+func NewScimMeta(resourceType string, createAt int64, updateAt int64, location string) *ScimMeta {
+	return &ScimMeta{
+		ResourceType: resourceType,
+		Created:      scimTime(createAt),
+		LastModified: scimTime(updateAt),
+		Location:     location,
+		Version:      fmt.Sprintf("W/\"%v\"", updateAt),
+	}
+}
+
+func scimTime(millis int64) string {
+	return time.Unix(0, millis*int64(time.Millisecond)).UTC().Format(time.RFC3339)
+}
+
+type ScimName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+type ScimEmail struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type ScimUser struct {
+	Schemas    []string    `json:"schemas"`
+	Id         string      `json:"id,omitempty"`
+	ExternalId string      `json:"externalId,omitempty"`
+	UserName   string      `json:"userName"`
+	Name       *ScimName   `json:"name,omitempty"`
+	NickName   string      `json:"nickName,omitempty"`
+	Title      string      `json:"title,omitempty"`
+	Emails     []ScimEmail `json:"emails,omitempty"`
+	Active     *bool       `json:"active,omitempty"`
+	Meta       *ScimMeta   `json:"meta,omitempty"`
+}
+
+// PrimaryEmail returns the email marked as primary, falling back to the first email if none of them are.
+func (u *ScimUser) PrimaryEmail() string {
+	for _, email := range u.Emails {
+		if email.Primary {
+			return email.Value
+		}
+	}
+
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+
+	return ""
+}
+
+func (u *ScimUser) ToJson() string {
+	b, _ := json.Marshal(u)
+	return string(b)
+}
+
+func ScimUserFromJson(data io.Reader) *ScimUser {
+	var u *ScimUser
+	json.NewDecoder(data).Decode(&u)
+	return u
+}
+
+type ScimMember struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+type ScimGroup struct {
+	Schemas     []string     `json:"schemas"`
+	Id          string       `json:"id,omitempty"`
+	ExternalId  string       `json:"externalId,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []ScimMember `json:"members"`
+	Meta        *ScimMeta    `json:"meta,omitempty"`
+}
+
+// MemberIds returns the ids of the group's members without any duplicates.
+func (g *ScimGroup) MemberIds() []string {
+	seen := map[string]bool{}
+	ids := []string{}
+
+	for _, member := range g.Members {
+		if member.Value != "" && !seen[member.Value] {
+			seen[member.Value] = true
+			ids = append(ids, member.Value)
+		}
+	}
+
+	return ids
+}
+
+func (g *ScimGroup) ToJson() string {
+	b, _ := json.Marshal(g)
+	return string(b)
+}
+
+func ScimGroupFromJson(data io.Reader) *ScimGroup {
+	var g *ScimGroup
+	json.NewDecoder(data).Decode(&g)
+	return g
+}
+
+type ScimListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int           `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+func NewScimListResponse(resources []interface{}, totalResults int, startIndex int) *ScimListResponse {
+	return &ScimListResponse{
+		Schemas:      []string{SCIM_SCHEMA_LIST_RESPONSE},
+		TotalResults: totalResults,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+func (r *ScimListResponse) ToJson() string {
+	b, _ := json.Marshal(r)
+	return string(b)
+}
+
+type ScimError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+func NewScimError(err *AppError, scimType string) *ScimError {
+	return &ScimError{
+		Schemas:  []string{SCIM_SCHEMA_ERROR},
+		Status:   strconv.Itoa(err.StatusCode),
+		ScimType: scimType,
+		Detail:   err.Message,
+	}
+}
+
+func (e *ScimError) ToJson() string {
+	b, _ := json.Marshal(e)
+	return string(b)
+}
+
+type ScimSupported struct {
+	Supported bool `json:"supported"`
+}
+
+type ScimBulkSupported struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+type ScimFilterSupported struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type ScimAuthenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type ScimServiceProviderConfig struct {
+	Schemas               []string                   `json:"schemas"`
+	Patch                 ScimSupported              `json:"patch"`
+	Bulk                  ScimBulkSupported          `json:"bulk"`
+	Filter                ScimFilterSupported        `json:"filter"`
+	ChangePassword        ScimSupported              `json:"changePassword"`
+	Sort                  ScimSupported              `json:"sort"`
+	Etag                  ScimSupported              `json:"etag"`
+	AuthenticationSchemes []ScimAuthenticationScheme `json:"authenticationSchemes"`
+}
+
+// NewScimServiceProviderConfig describes the parts of SCIM that the server supports.
+func NewScimServiceProviderConfig() *ScimServiceProviderConfig {
+	return &ScimServiceProviderConfig{
+		Schemas: []string{SCIM_SCHEMA_SERVICE_PROVIDER_CONFIG},
+		Patch:   ScimSupported{Supported: true},
+		Filter:  ScimFilterSupported{Supported: true, MaxResults: SCIM_MAX_COUNT},
+		Etag:    ScimSupported{Supported: true},
+		AuthenticationSchemes: []ScimAuthenticationScheme{
+			{
+				Type:        "oauthbearertoken",
+				Name:        "Provisioning Token",
+				Description: "Authentication with a provisioning token created by a system admin",
+			},
+		},
+	}
+}
+
+func (c *ScimServiceProviderConfig) ToJson() string {
+	b, _ := json.Marshal(c)
+	return string(b)
+}
+
+// ScimFilter is a filter of the form `attribute eq "value"`, which is the only kind of filter that's supported.
+type ScimFilter struct {
+	Attribute string
+	Value     string
+}
+
+func ParseScimFilter(filter string) (*ScimFilter, *AppError) {
+	matches := scimFilterPattern.FindStringSubmatch(filter)
+	if matches == nil {
+		return nil, NewAppError("ParseScimFilter", "model.scim.parse_filter.app_error", nil, "filter="+filter, http.StatusBadRequest)
+	}
+
+	value, err := strconv.Unquote(matches[2])
+	if err != nil {
+		return nil, NewAppError("ParseScimFilter", "model.scim.parse_filter.app_error", nil, "filter="+filter+", "+err.Error(), http.StatusBadRequest)
+	}
+
+	return &ScimFilter{Attribute: matches[1], Value: value}, nil
+}
+
+// Matches returns true if the resource has the filtered attribute set to the filtered value. Attribute names are
+// case insensitive.
+func (f *ScimFilter) Matches(resource map[string]interface{}) bool {
+	path := strings.Split(f.Attribute, ".")
+
+	var value interface{} = resource
+	for _, name := range path {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+
+		key := scimFindKey(object, name)
+		if key == "" {
+			return false
+		}
+
+		value = object[key]
+	}
+
+	return fmt.Sprint(value) == f.Value
+}
+
+type ScimPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+type ScimPatchRequest struct {
+	Schemas    []string              `json:"schemas"`
+	Operations []*ScimPatchOperation `json:"Operations"`
+}
+
+func (r *ScimPatchRequest) ToJson() string {
+	b, _ := json.Marshal(r)
+	return string(b)
+}
+
+func ScimPatchRequestFromJson(data io.Reader) *ScimPatchRequest {
+	var r *ScimPatchRequest
+	json.NewDecoder(data).Decode(&r)
+	return r
+}
+
+// ApplyToUser returns a copy of the user with the patch operations applied to it.
+func (r *ScimPatchRequest) ApplyToUser(user *ScimUser) (*ScimUser, *AppError) {
+	data, err := r.apply(user)
+	if err != nil {
+		return nil, err
+	}
+
+	var patched *ScimUser
+	if err := json.Unmarshal(data, &patched); err != nil {
+		return nil, NewAppError("ScimPatchRequest.ApplyToUser", "model.scim.patch.invalid_value.app_error", nil, err.Error(), http.StatusBadRequest)
+	}
+
+	return patched, nil
+}
+
+// ApplyToGroup returns a copy of the group with the patch operations applied to it.
+func (r *ScimPatchRequest) ApplyToGroup(group *ScimGroup) (*ScimGroup, *AppError) {
+	data, err := r.apply(group)
+	if err != nil {
+		return nil, err
+	}
+
+	var patched *ScimGroup
+	if err := json.Unmarshal(data, &patched); err != nil {
+		return nil, NewAppError("ScimPatchRequest.ApplyToGroup", "model.scim.patch.invalid_value.app_error", nil, err.Error(), http.StatusBadRequest)
+	}
+
+	return patched, nil
+}
+
+// apply runs the operations against the resource's JSON representation so that the same paths work for any kind
+// of resource.
+func (r *ScimPatchRequest) apply(resource interface{}) ([]byte, *AppError) {
+	b, _ := json.Marshal(resource)
+
+	var object map[string]interface{}
+	json.Unmarshal(b, &object)
+
+	if len(r.Operations) == 0 {
+		return nil, NewAppError("ScimPatchRequest.apply", "model.scim.patch.no_operations.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	for _, operation := range r.Operations {
+		op := strings.ToLower(operation.Op)
+		if op != SCIM_PATCH_OP_ADD && op != SCIM_PATCH_OP_REPLACE && op != SCIM_PATCH_OP_REMOVE {
+			return nil, NewAppError("ScimPatchRequest.apply", "model.scim.patch.invalid_op.app_error", nil, "op="+operation.Op, http.StatusBadRequest)
+		}
+
+		if operation.Path == "" {
+			// without a path, the value is an object of attributes to add or replace
+			values, ok := operation.Value.(map[string]interface{})
+			if !ok || op == SCIM_PATCH_OP_REMOVE {
+				return nil, NewAppError("ScimPatchRequest.apply", "model.scim.patch.invalid_path.app_error", nil, "op="+operation.Op, http.StatusBadRequest)
+			}
+
+			for path, value := range values {
+				if err := scimPatchPath(object, op, path, value); err != nil {
+					return nil, err
+				}
+			}
+		} else if err := scimPatchPath(object, op, operation.Path, operation.Value); err != nil {
+			return nil, err
+		}
+	}
+
+	b, _ = json.Marshal(object)
+	return b, nil
+}
+
+// scimPatchPath applies an operation to a path of the form `attribute`, `attribute.subAttribute`,
+// `attribute[filter]` or `attribute[filter].subAttribute`.
+func scimPatchPath(object map[string]interface{}, op string, path string, value interface{}) *AppError {
+	// strip a leading schema URN, like urn:ietf:params:scim:schemas:core:2.0:User:userName
+	if strings.HasPrefix(path, "urn:") {
+		path = path[strings.LastIndex(path, ":")+1:]
+	}
+
+	matches := scimPathPattern.FindStringSubmatch(path)
+	if matches == nil {
+		return NewAppError("scimPatchPath", "model.scim.patch.invalid_path.app_error", nil, "path="+path, http.StatusBadRequest)
+	}
+	attribute, filterString, subAttribute := matches[1], matches[2], matches[3]
+
+	key := scimFindKey(object, attribute)
+	if key == "" {
+		key = attribute
+	}
+
+	if filterString == "" {
+		if subAttribute == "" {
+			scimPatchValue(object, op, key, value)
+			return nil
+		}
+
+		child, ok := object[key].(map[string]interface{})
+		if !ok {
+			if op == SCIM_PATCH_OP_REMOVE {
+				return nil
+			}
+
+			child = map[string]interface{}{}
+			object[key] = child
+		}
+
+		scimPatchValue(child, op, scimFindKeyOrDefault(child, subAttribute), value)
+		return nil
+	}
+
+	filter, err := ParseScimFilter(filterString)
+	if err != nil {
+		return NewAppError("scimPatchPath", "model.scim.patch.invalid_path.app_error", nil, "path="+path, http.StatusBadRequest)
+	}
+
+	items, _ := object[key].([]interface{})
+	remaining := make([]interface{}, 0, len(items))
+	matched := false
+
+	for _, item := range items {
+		itemObject, ok := item.(map[string]interface{})
+		if !ok || !filter.Matches(itemObject) {
+			remaining = append(remaining, item)
+			continue
+		}
+
+		matched = true
+
+		if subAttribute != "" {
+			scimPatchValue(itemObject, op, scimFindKeyOrDefault(itemObject, subAttribute), value)
+			remaining = append(remaining, itemObject)
+		} else if op != SCIM_PATCH_OP_REMOVE {
+			remaining = append(remaining, value)
+		}
+	}
+
+	if !matched {
+		if op == SCIM_PATCH_OP_REMOVE {
+			return nil
+		}
+
+		return NewAppError("scimPatchPath", "model.scim.patch.no_target.app_error", nil, "path="+path, http.StatusBadRequest)
+	}
+
+	object[key] = remaining
+	return nil
+}
+
+// scimPatchValue applies an operation to a single attribute. Adding to a multi-valued attribute appends to it, and
+// removing values from one removes the entries with the same value.
+func scimPatchValue(object map[string]interface{}, op string, key string, value interface{}) {
+	existing, isList := object[key].([]interface{})
+
+	switch op {
+	case SCIM_PATCH_OP_ADD:
+		if values, ok := value.([]interface{}); ok && isList {
+			object[key] = append(existing, values...)
+		} else {
+			object[key] = value
+		}
+	case SCIM_PATCH_OP_REPLACE:
+		object[key] = value
+	case SCIM_PATCH_OP_REMOVE:
+		values, ok := value.([]interface{})
+		if !ok || !isList {
+			delete(object, key)
+			return
+		}
+
+		removed := map[string]bool{}
+		for _, v := range values {
+			if entry, ok := v.(map[string]interface{}); ok {
+				removed[fmt.Sprint(entry["value"])] = true
+			}
+		}
+
+		remaining := make([]interface{}, 0, len(existing))
+		for _, item := range existing {
+			if entry, ok := item.(map[string]interface{}); ok && removed[fmt.Sprint(entry["value"])] {
+				continue
+			}
+			remaining = append(remaining, item)
+		}
+		object[key] = remaining
+	}
+}
+
+// scimFindKey returns the key in the object matching the attribute name, since attribute names are case
+// insensitive, or an empty string if there isn't one.
+func scimFindKey(object map[string]interface{}, name string) string {
+	if _, ok := object[name]; ok {
+		return name
+	}
+
+	for key := range object {
+		if strings.EqualFold(key, name) {
+			return key
+		}
+	}
+
+	return ""
+}
+
+func scimFindKeyOrDefault(object map[string]interface{}, name string) string {
+	if key := scimFindKey(object, name); key != "" {
+		return key
+	}
+
+	return name
+}
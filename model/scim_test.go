@@ -0,0 +1,154 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScimFilter(t *testing.T) {
+	filter, err := ParseScimFilter(`userName eq "bjensen@example.com"`)
+	require.Nil(t, err)
+	assert.Equal(t, "userName", filter.Attribute)
+	assert.Equal(t, "bjensen@example.com", filter.Value)
+
+	filter, err = ParseScimFilter(`emails.value EQ "a \"quoted\" value"`)
+	require.Nil(t, err)
+	assert.Equal(t, "emails.value", filter.Attribute)
+	assert.Equal(t, `a "quoted" value`, filter.Value)
+
+	for _, invalid := range []string{"", `userName`, `userName eq bjensen`, `userName sw "b"`, `userName eq "a" and id eq "b"`} {
+		_, err := ParseScimFilter(invalid)
+		if assert.NotNil(t, err, invalid) {
+			assert.Equal(t, "model.scim.parse_filter.app_error", err.Id)
+		}
+	}
+}
+
+func TestScimFilterMatches(t *testing.T) {
+	resource := map[string]interface{}{
+		"value":   "user1",
+		"display": "User",
+		"name":    map[string]interface{}{"givenName": "Barbara"},
+	}
+
+	assert.True(t, (&ScimFilter{Attribute: "value", Value: "user1"}).Matches(resource))
+	assert.True(t, (&ScimFilter{Attribute: "Display", Value: "User"}).Matches(resource))
+	assert.True(t, (&ScimFilter{Attribute: "name.givenname", Value: "Barbara"}).Matches(resource))
+	assert.False(t, (&ScimFilter{Attribute: "value", Value: "user2"}).Matches(resource))
+	assert.False(t, (&ScimFilter{Attribute: "missing", Value: "user1"}).Matches(resource))
+	assert.False(t, (&ScimFilter{Attribute: "value.missing", Value: "user1"}).Matches(resource))
+}
+
+func TestNewScimMeta(t *testing.T) {
+	meta := NewScimMeta(SCIM_RESOURCE_TYPE_USER, 1000, 2000, "http://localhost/scim/v2/Users/id")
+	assert.Equal(t, SCIM_RESOURCE_TYPE_USER, meta.ResourceType)
+	assert.Equal(t, "http://localhost/scim/v2/Users/id", meta.Location)
+	assert.Equal(t, `W/"2000"`, meta.Version)
+	assert.NotEqual(t, meta.Version, NewScimMeta(SCIM_RESOURCE_TYPE_USER, 1000, 3000, "").Version)
+	assert.NotEmpty(t, meta.Created)
+	assert.NotEmpty(t, meta.LastModified)
+}
+
+func TestScimUserPrimaryEmail(t *testing.T) {
+	user := &ScimUser{}
+	assert.Equal(t, "", user.PrimaryEmail())
+
+	user.Emails = []ScimEmail{{Value: "home@example.com"}, {Value: "work@example.com", Primary: true}}
+	assert.Equal(t, "work@example.com", user.PrimaryEmail())
+
+	user.Emails = []ScimEmail{{Value: "home@example.com"}}
+	assert.Equal(t, "home@example.com", user.PrimaryEmail())
+}
+
+func TestScimPatchRequestApplyToUser(t *testing.T) {
+	user := &ScimUser{
+		Schemas:  []string{SCIM_SCHEMA_USER},
+		UserName: "bjensen",
+		Name:     &ScimName{GivenName: "Barbara", FamilyName: "Jensen"},
+		Emails:   []ScimEmail{{Value: "bjensen@example.com", Type: SCIM_EMAIL_TYPE_WORK, Primary: true}},
+		Active:   NewBool(true),
+	}
+
+	patch := ScimPatchRequestFromJson(strings.NewReader(`{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [
+			{"op": "Replace", "path": "active", "value": false},
+			{"op": "replace", "path": "name.familyName", "value": "Smith"},
+			{"op": "replace", "path": "emails[type eq \"work\"].value", "value": "bsmith@example.com"},
+			{"op": "add", "value": {"nickName": "Babs", "urn:ietf:params:scim:schemas:core:2.0:User:title": "Engineer"}}
+		]
+	}`))
+	require.NotNil(t, patch)
+
+	patched, err := patch.ApplyToUser(user)
+	require.Nil(t, err)
+	assert.False(t, *patched.Active)
+	assert.Equal(t, "Barbara", patched.Name.GivenName)
+	assert.Equal(t, "Smith", patched.Name.FamilyName)
+	assert.Equal(t, "bsmith@example.com", patched.PrimaryEmail())
+	assert.Equal(t, "Babs", patched.NickName)
+	assert.Equal(t, "Engineer", patched.Title)
+
+	// the original user isn't changed
+	assert.True(t, *user.Active)
+	assert.Equal(t, "Jensen", user.Name.FamilyName)
+
+	patched, err = (&ScimPatchRequest{Operations: []*ScimPatchOperation{{Op: "remove", Path: "nickName"}}}).ApplyToUser(patched)
+	require.Nil(t, err)
+	assert.Equal(t, "", patched.NickName)
+
+	for id, operations := range map[string][]*ScimPatchOperation{
+		"model.scim.patch.no_operations.app_error": {},
+		"model.scim.patch.invalid_op.app_error":    {{Op: "move", Path: "active", Value: true}},
+		"model.scim.patch.invalid_path.app_error":  {{Op: "replace", Path: "emails[type]", Value: "x"}},
+		"model.scim.patch.no_target.app_error":     {{Op: "replace", Path: "emails[type eq \"home\"].value", Value: "x"}},
+		"model.scim.patch.invalid_value.app_error": {{Op: "replace", Path: "active", Value: "yes"}},
+	} {
+		_, err := (&ScimPatchRequest{Operations: operations}).ApplyToUser(user)
+		if assert.NotNil(t, err, id) {
+			assert.Equal(t, id, err.Id)
+		}
+	}
+}
+
+func TestScimPatchRequestApplyToGroup(t *testing.T) {
+	group := &ScimGroup{
+		DisplayName: "Engineering",
+		Members:     []ScimMember{{Value: "user1"}, {Value: "user2"}},
+	}
+
+	patched, err := (&ScimPatchRequest{Operations: []*ScimPatchOperation{
+		{Op: "add", Path: "members", Value: []interface{}{map[string]interface{}{"value": "user3"}}},
+		{Op: "remove", Path: "members[value eq \"user1\"]"},
+	}}).ApplyToGroup(group)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"user2", "user3"}, patched.MemberIds())
+
+	patched, err = (&ScimPatchRequest{Operations: []*ScimPatchOperation{
+		{Op: "remove", Path: "members", Value: []interface{}{map[string]interface{}{"value": "user2"}}},
+		{Op: "replace", Path: "displayName", Value: "Design"},
+	}}).ApplyToGroup(patched)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"user3"}, patched.MemberIds())
+	assert.Equal(t, "Design", patched.DisplayName)
+
+	patched, err = (&ScimPatchRequest{Operations: []*ScimPatchOperation{
+		{Op: "remove", Path: "members"},
+	}}).ApplyToGroup(patched)
+	require.Nil(t, err)
+	assert.Empty(t, patched.MemberIds())
+}
+
+func TestNewScimError(t *testing.T) {
+	scimErr := NewScimError(NewAppError("where", "id", nil, "", 409), SCIM_ERROR_TYPE_UNIQUENESS)
+	assert.Equal(t, []string{SCIM_SCHEMA_ERROR}, scimErr.Schemas)
+	assert.Equal(t, "409", scimErr.Status)
+	assert.Equal(t, SCIM_ERROR_TYPE_UNIQUENESS, scimErr.ScimType)
+	assert.Contains(t, scimErr.ToJson(), `"status":"409"`)
+}
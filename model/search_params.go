@@ -17,6 +17,13 @@ type SearchParams struct {
 	InChannels []string
 	FromUsers  []string
 	OrTerms    bool
+
+	// MaxCreateAt, if set, leaves out posts created after it so that new posts don't move the results of a
+	// search between pages.
+	MaxCreateAt int64
+
+	// Limit is the most posts to return, or POST_SEARCH_DEFAULT_LIMIT if it isn't set.
+	Limit int
 }
 
 var searchFlags = [...]string{"from", "channel", "in"}
@@ -0,0 +1,39 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// SeatReport shows how many of the server's licensed seats are in use and how they're spread across teams.
+type SeatReport struct {
+	// ActiveUserCount is the number of active users that aren't bots. Each user is only counted once no matter how
+	// many teams they're in.
+	ActiveUserCount      int64 `json:"active_user_count"`
+	BotCount             int64 `json:"bot_count"`
+	DeactivatedUserCount int64 `json:"deactivated_user_count"`
+
+	Teams []*TeamSeatCount `json:"teams"`
+}
+
+// TeamSeatCount is the number of active members of a team, including any bots that have been added to it.
+type TeamSeatCount struct {
+	TeamId            string `json:"team_id"`
+	Name              string `json:"name"`
+	DisplayName       string `json:"display_name"`
+	ActiveMemberCount int64  `json:"active_member_count"`
+}
+
+func (o *SeatReport) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func SeatReportFromJson(data io.Reader) *SeatReport {
+	var o *SeatReport
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
@@ -10,17 +10,22 @@ import (
 )
 
 const (
-	SESSION_COOKIE_TOKEN              = "MMAUTHTOKEN"
-	SESSION_COOKIE_USER               = "MMUSERID"
-	SESSION_CACHE_SIZE                = 35000
-	SESSION_PROP_PLATFORM             = "platform"
-	SESSION_PROP_OS                   = "os"
-	SESSION_PROP_BROWSER              = "browser"
-	SESSION_PROP_TYPE                 = "type"
-	SESSION_PROP_USER_ACCESS_TOKEN_ID = "user_access_token_id"
-	SESSION_TYPE_USER_ACCESS_TOKEN    = "UserAccessToken"
-	SESSION_ACTIVITY_TIMEOUT          = 1000 * 60 * 5 // 5 minutes
-	SESSION_USER_ACCESS_TOKEN_EXPIRY  = 100 * 365     // 100 years
+	SESSION_COOKIE_TOKEN                 = "MMAUTHTOKEN"
+	SESSION_COOKIE_USER                  = "MMUSERID"
+	SESSION_CACHE_SIZE                   = 35000
+	SESSION_PROP_PLATFORM                = "platform"
+	SESSION_PROP_OS                      = "os"
+	SESSION_PROP_BROWSER                 = "browser"
+	SESSION_PROP_TYPE                    = "type"
+	SESSION_PROP_USER_ACCESS_TOKEN_ID    = "user_access_token_id"
+	SESSION_PROP_IMPERSONATOR_ID         = "impersonator_id"
+	SESSION_PROP_IMPERSONATOR_SESSION_ID = "impersonator_session_id"
+	SESSION_PROP_FINGERPRINT_AGENT       = "fingerprint_agent"
+	SESSION_PROP_FINGERPRINT_IP          = "fingerprint_ip"
+	SESSION_PROP_ANOMALY_DETECTED_AT     = "anomaly_detected_at"
+	SESSION_TYPE_USER_ACCESS_TOKEN       = "UserAccessToken"
+	SESSION_ACTIVITY_TIMEOUT             = 1000 * 60 * 5 // 5 minutes
+	SESSION_USER_ACCESS_TOKEN_EXPIRY     = 100 * 365     // 100 years
 )
 
 type Session struct {
@@ -35,6 +40,14 @@ type Session struct {
 	IsOAuth        bool          `json:"is_oauth"`
 	Props          StringMap     `json:"props"`
 	TeamMembers    []*TeamMember `json:"team_members" db:"-"`
+
+	// IsImpersonation is set on sessions that a system admin uses to act as another user. The admin and
+	// their own session are recorded in the session's props.
+	IsImpersonation bool `json:"is_impersonation"`
+
+	// TermsOfServiceRequired is set when the user must accept the latest custom terms of service before
+	// using the server.
+	TermsOfServiceRequired bool `json:"terms_of_service_required,omitempty" db:"-"`
 }
 
 func (me *Session) DeepCopy() *Session {
@@ -131,6 +144,10 @@ func (me *Session) IsMobileApp() bool {
 	return len(me.DeviceId) > 0
 }
 
+func (me *Session) IsGuest() bool {
+	return IsInRole(me.Roles, SYSTEM_GUEST_ROLE_ID)
+}
+
 func (me *Session) GetUserRoles() []string {
 	return strings.Fields(me.Roles)
 }
@@ -0,0 +1,77 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"net/http"
+)
+
+const (
+	// SHORT_PERMALINK_CODE_LENGTH is long enough, at 80 random bits, that codes can't be found by guessing them
+	// when lookups are rate limited.
+	SHORT_PERMALINK_CODE_LENGTH = 16
+
+	SHORT_PERMALINK_PATH = "/plink/"
+)
+
+// ShortPermalink maps a short code to the permalink of a post, so that notifications sent through channels that
+// truncate long URLs, such as SMS gateways, still link to the post. TeamId is the team that the permalink opens the
+// post in, since direct and group channels aren't on a team.
+type ShortPermalink struct {
+	Code     string `json:"code"`
+	PostId   string `json:"post_id"`
+	TeamId   string `json:"team_id"`
+	CreateAt int64  `json:"create_at"`
+	ExpireAt int64  `json:"expire_at"`
+}
+
+func (l *ShortPermalink) PreSave() {
+	l.Code = NewRandomString(SHORT_PERMALINK_CODE_LENGTH)
+	l.CreateAt = GetMillis()
+}
+
+func (l *ShortPermalink) IsValid() *AppError {
+	if !IsValidShortPermalinkCode(l.Code) {
+		return NewAppError("ShortPermalink.IsValid", "model.short_permalink.is_valid.code.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if len(l.PostId) != 26 {
+		return NewAppError("ShortPermalink.IsValid", "model.short_permalink.is_valid.post_id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if len(l.TeamId) != 26 {
+		return NewAppError("ShortPermalink.IsValid", "model.short_permalink.is_valid.team_id.app_error", nil, "post_id="+l.PostId, http.StatusBadRequest)
+	}
+
+	if l.CreateAt == 0 {
+		return NewAppError("ShortPermalink.IsValid", "model.short_permalink.is_valid.create_at.app_error", nil, "post_id="+l.PostId, http.StatusBadRequest)
+	}
+
+	if l.ExpireAt <= l.CreateAt {
+		return NewAppError("ShortPermalink.IsValid", "model.short_permalink.is_valid.expire_at.app_error", nil, "post_id="+l.PostId, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+// IsExpired returns true if the code can no longer be used at the given time, in milliseconds.
+func (l *ShortPermalink) IsExpired(now int64) bool {
+	return l.ExpireAt <= now
+}
+
+// IsValidShortPermalinkCode checks that a code could have been generated, so that lookups for ones that couldn't
+// are refused without a query.
+func IsValidShortPermalinkCode(code string) bool {
+	if len(code) != SHORT_PERMALINK_CODE_LENGTH {
+		return false
+	}
+
+	for _, r := range code {
+		if !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') {
+			return false
+		}
+	}
+
+	return true
+}
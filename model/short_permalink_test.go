@@ -0,0 +1,49 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShortPermalinkIsValid(t *testing.T) {
+	link := &ShortPermalink{PostId: NewId(), TeamId: NewId()}
+	link.PreSave()
+	link.ExpireAt = link.CreateAt + 1000
+	require.Nil(t, link.IsValid())
+
+	for id, change := range map[string]func(*ShortPermalink){
+		"model.short_permalink.is_valid.code.app_error":      func(l *ShortPermalink) { l.Code = "" },
+		"model.short_permalink.is_valid.post_id.app_error":   func(l *ShortPermalink) { l.PostId = "" },
+		"model.short_permalink.is_valid.team_id.app_error":   func(l *ShortPermalink) { l.TeamId = "" },
+		"model.short_permalink.is_valid.create_at.app_error": func(l *ShortPermalink) { l.CreateAt = 0 },
+		"model.short_permalink.is_valid.expire_at.app_error": func(l *ShortPermalink) { l.ExpireAt = l.CreateAt },
+	} {
+		invalid := *link
+		change(&invalid)
+
+		err := invalid.IsValid()
+		if assert.NotNil(t, err, id) {
+			assert.Equal(t, id, err.Id)
+		}
+	}
+
+	assert.False(t, link.IsExpired(link.CreateAt))
+	assert.True(t, link.IsExpired(link.ExpireAt))
+}
+
+func TestIsValidShortPermalinkCode(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		assert.True(t, IsValidShortPermalinkCode(NewRandomString(SHORT_PERMALINK_CODE_LENGTH)))
+	}
+
+	assert.False(t, IsValidShortPermalinkCode(""))
+	assert.False(t, IsValidShortPermalinkCode(NewRandomString(SHORT_PERMALINK_CODE_LENGTH-1)))
+	assert.False(t, IsValidShortPermalinkCode(strings.ToUpper(NewRandomString(SHORT_PERMALINK_CODE_LENGTH))))
+	assert.False(t, IsValidShortPermalinkCode("abcdefgh/jklmnop"))
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/json"
+	"io"
+	"net/http"
+	"unicode/utf8"
+)
+
+const (
+	SIDEBAR_CATEGORY_FAVORITES       = "favorites"
+	SIDEBAR_CATEGORY_CHANNELS        = "channels"
+	SIDEBAR_CATEGORY_DIRECT_MESSAGES = "direct_messages"
+	SIDEBAR_CATEGORY_CUSTOM          = "custom"
+
+	SIDEBAR_CATEGORY_DISPLAY_NAME_MAX_RUNES = 64
+)
+
+// SidebarCategory is a group of channels shown together in a user's channel sidebar for a team.
+type SidebarCategory struct {
+	Id          string `json:"id"`
+	UserId      string `json:"user_id"`
+	TeamId      string `json:"team_id"`
+	SortOrder   int64  `json:"sort_order"`
+	Type        string `json:"type"`
+	DisplayName string `json:"display_name"`
+}
+
+// SidebarChannel records that a channel has been placed in a sidebar category.
+type SidebarChannel struct {
+	ChannelId  string `json:"channel_id"`
+	UserId     string `json:"user_id"`
+	CategoryId string `json:"category_id"`
+	SortOrder  int64  `json:"sort_order"`
+}
+
+type SidebarCategoryWithChannels struct {
+	SidebarCategory
+	Channels []string `json:"channel_ids"`
+}
+
+type OrderedSidebarCategories struct {
+	Categories []*SidebarCategoryWithChannels `json:"categories"`
+	Order      []string                       `json:"order"`
+}
+
+func IsValidSidebarCategoryType(categoryType string) bool {
+	switch categoryType {
+	case SIDEBAR_CATEGORY_FAVORITES, SIDEBAR_CATEGORY_CHANNELS, SIDEBAR_CATEGORY_DIRECT_MESSAGES, SIDEBAR_CATEGORY_CUSTOM:
+		return true
+	}
+
+	return false
+}
+
+// DefaultSidebarCategoryId returns the id of one of the categories that every user starts with on a team. It's the
+// same every time so that a user can't end up with two of them when they're created by requests at the same time.
+func DefaultSidebarCategoryId(categoryType string, userId string, teamId string) string {
+	hash := sha256.Sum256([]byte(categoryType + ":" + userId + ":" + teamId))
+
+	var b bytes.Buffer
+	encoder := base32.NewEncoder(encoding, &b)
+	encoder.Write(hash[:16])
+	encoder.Close()
+	b.Truncate(26) // removes the '==' padding
+	return b.String()
+}
+
+func (o *SidebarCategory) PreSave() {
+	if o.Id == "" {
+		o.Id = NewId()
+	}
+
+	if o.Type == "" {
+		o.Type = SIDEBAR_CATEGORY_CUSTOM
+	}
+}
+
+func (o *SidebarCategory) IsValid() *AppError {
+	if len(o.Id) != 26 {
+		return NewAppError("SidebarCategory.IsValid", "model.sidebar_category.is_valid.id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if len(o.UserId) != 26 {
+		return NewAppError("SidebarCategory.IsValid", "model.sidebar_category.is_valid.user_id.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	if len(o.TeamId) != 26 {
+		return NewAppError("SidebarCategory.IsValid", "model.sidebar_category.is_valid.team_id.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	if !IsValidSidebarCategoryType(o.Type) {
+		return NewAppError("SidebarCategory.IsValid", "model.sidebar_category.is_valid.type.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	if o.DisplayName == "" || utf8.RuneCountInString(o.DisplayName) > SIDEBAR_CATEGORY_DISPLAY_NAME_MAX_RUNES {
+		return NewAppError("SidebarCategory.IsValid", "model.sidebar_category.is_valid.display_name.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (o *SidebarCategoryWithChannels) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func SidebarCategoryWithChannelsFromJson(data io.Reader) *SidebarCategoryWithChannels {
+	var o *SidebarCategoryWithChannels
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
+
+func SidebarCategoriesWithChannelsToJson(o []*SidebarCategoryWithChannels) string {
+	if b, err := json.Marshal(o); err != nil {
+		return "[]"
+	} else {
+		return string(b)
+	}
+}
+
+func SidebarCategoriesWithChannelsFromJson(data io.Reader) []*SidebarCategoryWithChannels {
+	var o []*SidebarCategoryWithChannels
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
+
+func (o *OrderedSidebarCategories) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func OrderedSidebarCategoriesFromJson(data io.Reader) *OrderedSidebarCategories {
+	var o *OrderedSidebarCategories
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
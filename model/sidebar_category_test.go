@@ -0,0 +1,86 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSidebarCategoryIsValid(t *testing.T) {
+	category := SidebarCategory{
+		UserId:      NewId(),
+		TeamId:      NewId(),
+		DisplayName: "Category",
+	}
+	category.PreSave()
+
+	assert.Equal(t, SIDEBAR_CATEGORY_CUSTOM, category.Type)
+	assert.Nil(t, category.IsValid())
+
+	category.UserId = "junk"
+	assert.NotNil(t, category.IsValid())
+
+	category.UserId = NewId()
+	category.TeamId = ""
+	assert.NotNil(t, category.IsValid())
+
+	category.TeamId = NewId()
+	category.Type = "junk"
+	assert.NotNil(t, category.IsValid())
+
+	category.Type = SIDEBAR_CATEGORY_FAVORITES
+	category.DisplayName = ""
+	assert.NotNil(t, category.IsValid())
+
+	category.DisplayName = strings.Repeat("ü", SIDEBAR_CATEGORY_DISPLAY_NAME_MAX_RUNES)
+	assert.Nil(t, category.IsValid())
+
+	category.DisplayName += "a"
+	assert.NotNil(t, category.IsValid())
+}
+
+func TestDefaultSidebarCategoryId(t *testing.T) {
+	userId := NewId()
+	teamId := NewId()
+
+	id := DefaultSidebarCategoryId(SIDEBAR_CATEGORY_FAVORITES, userId, teamId)
+	assert.Len(t, id, 26)
+	assert.Equal(t, id, DefaultSidebarCategoryId(SIDEBAR_CATEGORY_FAVORITES, userId, teamId))
+	assert.NotEqual(t, id, DefaultSidebarCategoryId(SIDEBAR_CATEGORY_CHANNELS, userId, teamId))
+	assert.NotEqual(t, id, DefaultSidebarCategoryId(SIDEBAR_CATEGORY_FAVORITES, NewId(), teamId))
+	assert.NotEqual(t, id, DefaultSidebarCategoryId(SIDEBAR_CATEGORY_FAVORITES, userId, NewId()))
+
+	category := SidebarCategory{Id: id, UserId: userId, TeamId: teamId, Type: SIDEBAR_CATEGORY_FAVORITES, DisplayName: "Favorites"}
+	assert.Nil(t, category.IsValid())
+}
+
+func TestOrderedSidebarCategoriesJson(t *testing.T) {
+	category := &SidebarCategoryWithChannels{
+		SidebarCategory: SidebarCategory{
+			Id:          NewId(),
+			UserId:      NewId(),
+			TeamId:      NewId(),
+			Type:        SIDEBAR_CATEGORY_CUSTOM,
+			DisplayName: "Category",
+		},
+		Channels: []string{NewId(), NewId()},
+	}
+	categories := &OrderedSidebarCategories{
+		Categories: []*SidebarCategoryWithChannels{category},
+		Order:      []string{category.Id},
+	}
+
+	json := categories.ToJson()
+	assert.Contains(t, json, `"channel_ids":[`)
+
+	decoded := OrderedSidebarCategoriesFromJson(strings.NewReader(json))
+	require.NotNil(t, decoded)
+	assert.Equal(t, categories, decoded)
+
+	assert.Nil(t, SidebarCategoryWithChannelsFromJson(strings.NewReader("junk")))
+}
@@ -4,9 +4,24 @@
 package model
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"regexp"
 )
 
+const (
+	SLACK_ATTACHMENT_COLOR_GOOD    = "good"
+	SLACK_ATTACHMENT_COLOR_WARNING = "warning"
+	SLACK_ATTACHMENT_COLOR_DANGER  = "danger"
+
+	SLACK_ATTACHMENT_MARKDOWN_PRETEXT = "pretext"
+	SLACK_ATTACHMENT_MARKDOWN_TEXT    = "text"
+	SLACK_ATTACHMENT_MARKDOWN_FIELDS  = "fields"
+)
+
+var slackAttachmentHexColorRegex = regexp.MustCompile(`^#?([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
 type SlackAttachment struct {
 	Id         int64                   `json:"id"`
 	Fallback   string                  `json:"fallback"`
@@ -25,6 +40,36 @@ type SlackAttachment struct {
 	FooterIcon string                  `json:"footer_icon"`
 	Timestamp  interface{}             `json:"ts"` // This is either a string or an int64
 	Actions    []*PostAction           `json:"actions,omitempty"`
+
+	// MarkdownIn lists which of the pretext, text and fields are formatted with Markdown. All of them are when
+	// it's nil.
+	MarkdownIn []string `json:"mrkdwn_in,omitempty"`
+}
+
+// IsMarkdownIn returns whether the given section of the attachment should be formatted with Markdown.
+func (o *SlackAttachment) IsMarkdownIn(section string) bool {
+	if o.MarkdownIn == nil {
+		return true
+	}
+
+	for _, s := range o.MarkdownIn {
+		if s == section {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsValidSlackAttachmentColor returns whether the color is empty, a hex color code or one of the good, warning and
+// danger keywords.
+func IsValidSlackAttachmentColor(color string) bool {
+	switch color {
+	case "", SLACK_ATTACHMENT_COLOR_GOOD, SLACK_ATTACHMENT_COLOR_WARNING, SLACK_ATTACHMENT_COLOR_DANGER:
+		return true
+	}
+
+	return slackAttachmentHexColorRegex.MatchString(color)
 }
 
 type SlackAttachmentField struct {
@@ -57,3 +102,54 @@ func StringifySlackFieldValue(a []*SlackAttachment) []*SlackAttachment {
 	}
 	return nonNilAttachments
 }
+
+// ValidateSlackAttachments checks that the value of a post's attachments prop follows the message attachment
+// format so that clients are able to display it.
+func ValidateSlackAttachments(value interface{}) *AppError {
+	if attachments, ok := value.([]*SlackAttachment); ok {
+		for i, attachment := range attachments {
+			if attachment != nil && !IsValidSlackAttachmentColor(attachment.Color) {
+				return NewAppError("ValidateSlackAttachments", "model.slack_attachment.is_valid.color.app_error", map[string]interface{}{"Index": i, "Color": attachment.Color}, "", http.StatusBadRequest)
+			}
+		}
+
+		return nil
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return NewAppError("ValidateSlackAttachments", "model.slack_attachment.is_valid.not_array.app_error", nil, err.Error(), http.StatusBadRequest)
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(b, &items); err != nil {
+		return NewAppError("ValidateSlackAttachments", "model.slack_attachment.is_valid.not_array.app_error", nil, err.Error(), http.StatusBadRequest)
+	}
+
+	for i, item := range items {
+		var attachment *SlackAttachment
+		if err := json.Unmarshal(item, &attachment); err != nil {
+			if typeErr, ok := err.(*json.UnmarshalTypeError); ok && typeErr.Field != "" {
+				return NewAppError("ValidateSlackAttachments", "model.slack_attachment.is_valid.field_type.app_error", map[string]interface{}{"Index": i, "Field": typeErr.Field}, err.Error(), http.StatusBadRequest)
+			}
+
+			return NewAppError("ValidateSlackAttachments", "model.slack_attachment.is_valid.app_error", map[string]interface{}{"Index": i}, err.Error(), http.StatusBadRequest)
+		}
+
+		if attachment == nil {
+			continue
+		}
+
+		switch attachment.Timestamp.(type) {
+		case nil, string, float64:
+		default:
+			return NewAppError("ValidateSlackAttachments", "model.slack_attachment.is_valid.field_type.app_error", map[string]interface{}{"Index": i, "Field": "ts"}, "", http.StatusBadRequest)
+		}
+
+		if !IsValidSlackAttachmentColor(attachment.Color) {
+			return NewAppError("ValidateSlackAttachments", "model.slack_attachment.is_valid.color.app_error", map[string]interface{}{"Index": i, "Color": attachment.Color}, "", http.StatusBadRequest)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateSlackAttachments(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Attachments   string
+		ExpectedError string
+		ExpectedField string
+	}{
+		"valid": {
+			Attachments: `[{"fallback": "test", "text": "hello", "fields": [{"title": "a", "value": 1, "short": true}], "ts": 1529000000}]`,
+		},
+		"string timestamp": {
+			Attachments: `[{"text": "hello", "ts": "1529000000"}]`,
+		},
+		"actions": {
+			Attachments: `[{"actions": [{"id": "action", "name": "Action", "integration": {"url": "http://localhost", "context": {"a": 1}}}]}]`,
+		},
+		"empty": {
+			Attachments: `[]`,
+		},
+		"null attachment": {
+			Attachments: `[null, {"text": "hello"}]`,
+		},
+		"color keywords": {
+			Attachments: `[{"color": "good"}, {"color": "warning"}, {"color": "danger"}]`,
+		},
+		"hex colors": {
+			Attachments: `[{"color": "#439FE0"}, {"color": "439fe0"}, {"color": "#abc"}]`,
+		},
+		"invalid color": {
+			Attachments:   `[{"color": "#439FE"}]`,
+			ExpectedError: "model.slack_attachment.is_valid.color.app_error",
+		},
+		"not a list": {
+			Attachments:   `{"text": "hello"}`,
+			ExpectedError: "model.slack_attachment.is_valid.not_array.app_error",
+		},
+		"attachment not an object": {
+			Attachments:   `["hello"]`,
+			ExpectedError: "model.slack_attachment.is_valid.app_error",
+		},
+		"text not a string": {
+			Attachments:   `[{"text": 5}]`,
+			ExpectedError: "model.slack_attachment.is_valid.field_type.app_error",
+			ExpectedField: "text",
+		},
+		"fields not a list": {
+			Attachments:   `[{"fields": "a"}]`,
+			ExpectedError: "model.slack_attachment.is_valid.field_type.app_error",
+			ExpectedField: "fields",
+		},
+		"field short not a bool": {
+			Attachments:   `[{"fields": [{"title": "a", "short": "yes"}]}]`,
+			ExpectedError: "model.slack_attachment.is_valid.field_type.app_error",
+			ExpectedField: "short",
+		},
+		"invalid timestamp": {
+			Attachments:   `[{"text": "hello", "ts": {"at": 1}}]`,
+			ExpectedError: "model.slack_attachment.is_valid.field_type.app_error",
+			ExpectedField: "ts",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			post := PostFromJson(strings.NewReader(`{"props": {"attachments": ` + tc.Attachments + `}}`))
+			require.NotNil(t, post)
+
+			err := ValidateSlackAttachments(post.Props["attachments"])
+			if tc.ExpectedError == "" {
+				assert.Nil(t, err)
+				return
+			}
+
+			require.NotNil(t, err)
+			assert.Equal(t, tc.ExpectedError, err.Id)
+			if tc.ExpectedField != "" {
+				// Newer versions of Go include the path to nested fields
+				assert.Contains(t, err.params["Field"], tc.ExpectedField)
+			}
+		})
+	}
+
+	t.Run("already decoded", func(t *testing.T) {
+		assert.Nil(t, ValidateSlackAttachments([]*SlackAttachment{{Text: "hello"}, nil}))
+
+		err := ValidateSlackAttachments([]*SlackAttachment{{Text: "hello", Color: "blue"}})
+		require.NotNil(t, err)
+		assert.Equal(t, "model.slack_attachment.is_valid.color.app_error", err.Id)
+	})
+}
+
+func TestSlackAttachmentIsMarkdownIn(t *testing.T) {
+	attachment := &SlackAttachment{}
+	assert.True(t, attachment.IsMarkdownIn(SLACK_ATTACHMENT_MARKDOWN_TEXT))
+	assert.True(t, attachment.IsMarkdownIn(SLACK_ATTACHMENT_MARKDOWN_FIELDS))
+
+	attachment.MarkdownIn = []string{SLACK_ATTACHMENT_MARKDOWN_PRETEXT, SLACK_ATTACHMENT_MARKDOWN_FIELDS}
+	assert.True(t, attachment.IsMarkdownIn(SLACK_ATTACHMENT_MARKDOWN_PRETEXT))
+	assert.True(t, attachment.IsMarkdownIn(SLACK_ATTACHMENT_MARKDOWN_FIELDS))
+	assert.False(t, attachment.IsMarkdownIn(SLACK_ATTACHMENT_MARKDOWN_TEXT))
+
+	attachment.MarkdownIn = []string{}
+	assert.False(t, attachment.IsMarkdownIn(SLACK_ATTACHMENT_MARKDOWN_TEXT))
+}
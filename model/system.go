@@ -16,6 +16,10 @@ const (
 	SYSTEM_ACTIVE_LICENSE_ID      = "ActiveLicenseId"
 	SYSTEM_LAST_COMPLIANCE_TIME   = "LastComplianceTime"
 	SYSTEM_ASYMMETRIC_SIGNING_KEY = "AsymmetricSigningKey"
+	SYSTEM_ANNOUNCEMENT           = "Announcement"
+	SYSTEM_BOT_USER_ID            = "SystemBotUserId"
+	SYSTEM_MAINTENANCE_MODE       = "MaintenanceMode"
+	SYSTEM_FEATURE_FLAG_OVERRIDES = "FeatureFlagOverrides"
 )
 
 type System struct {
@@ -23,6 +23,7 @@ const (
 	TEAM_EMAIL_MAX_LENGTH           = 128
 	TEAM_NAME_MAX_LENGTH            = 64
 	TEAM_NAME_MIN_LENGTH            = 2
+	TEAM_WELCOME_MESSAGE_MAX_RUNES  = 4000
 )
 
 type Team struct {
@@ -40,6 +41,13 @@ type Team struct {
 	InviteId           string `json:"invite_id"`
 	AllowOpenInvite    bool   `json:"allow_open_invite"`
 	LastTeamIconUpdate int64  `json:"last_team_icon_update,omitempty"`
+	// WelcomeMessage replaces AnnouncementSettings.WelcomeMessage for the users joining the team, if set.
+	WelcomeMessage string `json:"welcome_message"`
+	// InviteIdDisabled stops the team's InviteId from being used to join it, leaving only invitations and invite links.
+	InviteIdDisabled bool `json:"invite_id_disabled"`
+	// MemberCount is the number of active users in the team. It's kept up to date by the store as members join
+	// and leave, so it's ignored when a team is saved or updated.
+	MemberCount int64 `json:"member_count"`
 }
 
 type TeamPatch struct {
@@ -48,6 +56,9 @@ type TeamPatch struct {
 	CompanyName     *string `json:"company_name"`
 	InviteId        *string `json:"invite_id"`
 	AllowOpenInvite *bool   `json:"allow_open_invite"`
+	WelcomeMessage  *string `json:"welcome_message"`
+	// InviteIdDisabled stops the team's InviteId from being used to join it.
+	InviteIdDisabled *bool `json:"invite_id_disabled"`
 }
 
 type Invites struct {
@@ -164,6 +175,14 @@ func (o *Team) IsValid() *AppError {
 		return NewAppError("Team.IsValid", "model.team.is_valid.domains.app_error", nil, "id="+o.Id, http.StatusBadRequest)
 	}
 
+	if utf8.RuneCountInString(o.WelcomeMessage) > TEAM_WELCOME_MESSAGE_MAX_RUNES {
+		return NewAppError("Team.IsValid", "model.team.is_valid.welcome_message.app_error", map[string]interface{}{"MaxLength": TEAM_WELCOME_MESSAGE_MAX_RUNES}, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	if _, err := RenderWelcomeMessage(o.WelcomeMessage, WelcomeMessageValues{}); err != nil {
+		return NewAppError("Team.IsValid", "model.team.is_valid.welcome_message_template.app_error", nil, "id="+o.Id+", "+err.Error(), http.StatusBadRequest)
+	}
+
 	return nil
 }
 
@@ -174,6 +193,7 @@ func (o *Team) PreSave() {
 
 	o.CreateAt = GetMillis()
 	o.UpdateAt = o.CreateAt
+	o.MemberCount = 0
 
 	if len(o.InviteId) == 0 {
 		o.InviteId = NewId()
@@ -263,6 +283,14 @@ func (t *Team) Patch(patch *TeamPatch) {
 	if patch.AllowOpenInvite != nil {
 		t.AllowOpenInvite = *patch.AllowOpenInvite
 	}
+
+	if patch.WelcomeMessage != nil {
+		t.WelcomeMessage = *patch.WelcomeMessage
+	}
+
+	if patch.InviteIdDisabled != nil {
+		t.InviteIdDisabled = *patch.InviteIdDisabled
+	}
 }
 
 func (t *TeamPatch) ToJson() string {
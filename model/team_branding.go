@@ -0,0 +1,93 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+)
+
+const (
+	TEAM_BRANDING_DESCRIPTION_MAX_RUNES  = 1024
+	TEAM_BRANDING_WELCOME_TEXT_MAX_RUNES = 4000
+	TEAM_BRANDING_IMAGE_MAX_BYTES        = 2 * 1024 * 1024
+)
+
+// TeamBranding is the branding shown to users when they log in to or sign up for a team. It's used in place of the
+// site's branding, which is used for anything that a team hasn't set. Only system admins can change a team's
+// branding while it's locked.
+type TeamBranding struct {
+	TeamId          string `json:"team_id"`
+	Description     string `json:"description"`
+	WelcomeText     string `json:"welcome_text"`
+	Locked          bool   `json:"locked"`
+	UpdateAt        int64  `json:"update_at"`
+	LastImageUpdate int64  `json:"last_image_update"`
+}
+
+type TeamBrandingPatch struct {
+	Description *string `json:"description"`
+	WelcomeText *string `json:"welcome_text"`
+	Locked      *bool   `json:"locked"`
+}
+
+func (o *TeamBranding) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func TeamBrandingFromJson(data io.Reader) *TeamBranding {
+	var o *TeamBranding
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
+
+func (o *TeamBrandingPatch) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func TeamBrandingPatchFromJson(data io.Reader) *TeamBrandingPatch {
+	var o *TeamBrandingPatch
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
+
+func (o *TeamBranding) IsValid() *AppError {
+	if utf8.RuneCountInString(o.Description) > TEAM_BRANDING_DESCRIPTION_MAX_RUNES {
+		return NewAppError("TeamBranding.IsValid", "model.team_branding.is_valid.description.app_error", map[string]interface{}{"Max": TEAM_BRANDING_DESCRIPTION_MAX_RUNES}, "team_id="+o.TeamId, http.StatusBadRequest)
+	}
+
+	if utf8.RuneCountInString(o.WelcomeText) > TEAM_BRANDING_WELCOME_TEXT_MAX_RUNES {
+		return NewAppError("TeamBranding.IsValid", "model.team_branding.is_valid.welcome_text.app_error", map[string]interface{}{"Max": TEAM_BRANDING_WELCOME_TEXT_MAX_RUNES}, "team_id="+o.TeamId, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (o *TeamBranding) Patch(patch *TeamBrandingPatch) {
+	if patch.Description != nil {
+		o.Description = *patch.Description
+	}
+
+	if patch.WelcomeText != nil {
+		o.WelcomeText = *patch.WelcomeText
+	}
+
+	if patch.Locked != nil {
+		o.Locked = *patch.Locked
+	}
+}
+
+func (o *TeamBranding) Etag() string {
+	return strconv.FormatInt(o.UpdateAt, 10)
+}
+
+// ImageEtag identifies the version of the team's branding image.
+func (o *TeamBranding) ImageEtag() string {
+	return strconv.FormatInt(o.LastImageUpdate, 10)
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTeamBrandingJson(t *testing.T) {
+	o := TeamBranding{TeamId: NewId(), Description: "description", WelcomeText: "welcome", Locked: true, UpdateAt: GetMillis()}
+	ro := TeamBrandingFromJson(strings.NewReader(o.ToJson()))
+
+	assert.Equal(t, o, *ro)
+}
+
+func TestTeamBrandingIsValid(t *testing.T) {
+	o := TeamBranding{TeamId: NewId()}
+	assert.Nil(t, o.IsValid())
+
+	o.Description = strings.Repeat("ü", TEAM_BRANDING_DESCRIPTION_MAX_RUNES)
+	assert.Nil(t, o.IsValid())
+
+	o.Description += "a"
+	assert.NotNil(t, o.IsValid())
+
+	o.Description = ""
+	o.WelcomeText = strings.Repeat("ü", TEAM_BRANDING_WELCOME_TEXT_MAX_RUNES)
+	assert.Nil(t, o.IsValid())
+
+	o.WelcomeText += "a"
+	assert.NotNil(t, o.IsValid())
+}
+
+func TestTeamBrandingPatch(t *testing.T) {
+	o := TeamBranding{TeamId: NewId(), Description: "description", WelcomeText: "welcome"}
+
+	description := "new description"
+	locked := true
+	o.Patch(&TeamBrandingPatch{Description: &description, Locked: &locked})
+
+	assert.Equal(t, "new description", o.Description)
+	assert.Equal(t, "welcome", o.WelcomeText)
+	assert.True(t, o.Locked)
+}
+
+func TestTeamBrandingEtag(t *testing.T) {
+	o := TeamBranding{UpdateAt: 1234, LastImageUpdate: 5678}
+
+	assert.Equal(t, "1234", o.Etag())
+	assert.Equal(t, "5678", o.ImageEtag())
+}
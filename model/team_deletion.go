@@ -0,0 +1,98 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strconv"
+)
+
+const (
+	TEAM_DELETION_STAGE_CHANNELS    = "channels"
+	TEAM_DELETION_STAGE_WEBHOOKS    = "webhooks"
+	TEAM_DELETION_STAGE_COMMANDS    = "commands"
+	TEAM_DELETION_STAGE_PREFERENCES = "preferences"
+	TEAM_DELETION_STAGE_MEMBERS     = "members"
+	TEAM_DELETION_STAGE_VERIFY      = "verify"
+	TEAM_DELETION_STAGE_TEAM        = "team"
+	TEAM_DELETION_STAGE_DONE        = "done"
+
+	// Deleting the channels, along with their posts and files, takes up most of the time that deleting a team does
+	TEAM_DELETION_CHANNELS_PERCENT = 80
+)
+
+// TeamDeletionStages are the stages that a team is permanently deleted in, in order.
+var TeamDeletionStages = []string{
+	TEAM_DELETION_STAGE_CHANNELS,
+	TEAM_DELETION_STAGE_WEBHOOKS,
+	TEAM_DELETION_STAGE_COMMANDS,
+	TEAM_DELETION_STAGE_PREFERENCES,
+	TEAM_DELETION_STAGE_MEMBERS,
+	TEAM_DELETION_STAGE_VERIFY,
+	TEAM_DELETION_STAGE_TEAM,
+}
+
+// TeamDeletionProgress is how far the permanent deletion of a team has got. It's saved in the data of the job that
+// deletes the team, so that a deletion that was interrupted is resumed from the stage that it stopped at. Stage is
+// the stage that's being done, which is empty before the deletion has started.
+type TeamDeletionProgress struct {
+	TeamId             string `json:"team_id"`
+	Stage              string `json:"stage"`
+	ChannelsTotal      int64  `json:"channels_total"`
+	ChannelsDeleted    int64  `json:"channels_deleted"`
+	FilesDeleted       int64  `json:"files_deleted"`
+	PreferencesDeleted int64  `json:"preferences_deleted"`
+}
+
+// IsDone returns true if the team and everything on it has been deleted.
+func (p *TeamDeletionProgress) IsDone() bool {
+	return p.Stage == TEAM_DELETION_STAGE_DONE
+}
+
+// Percent is an estimate of how much of the deletion has been done, from 0 to 100.
+func (p *TeamDeletionProgress) Percent() int64 {
+	switch p.Stage {
+	case "":
+		return 0
+	case TEAM_DELETION_STAGE_DONE:
+		return 100
+	case TEAM_DELETION_STAGE_CHANNELS:
+		if p.ChannelsTotal == 0 || p.ChannelsDeleted >= p.ChannelsTotal {
+			return TEAM_DELETION_CHANNELS_PERCENT
+		}
+		return p.ChannelsDeleted * TEAM_DELETION_CHANNELS_PERCENT / p.ChannelsTotal
+	}
+
+	for i, stage := range TeamDeletionStages {
+		if stage == p.Stage {
+			return TEAM_DELETION_CHANNELS_PERCENT + int64(i-1)*(100-TEAM_DELETION_CHANNELS_PERCENT)/int64(len(TeamDeletionStages)-1)
+		}
+	}
+
+	return 0
+}
+
+// ToJobData saves the progress in the data of a job.
+func (p *TeamDeletionProgress) ToJobData(data map[string]string) {
+	data["team_id"] = p.TeamId
+	data["stage"] = p.Stage
+	data["channels_total"] = strconv.FormatInt(p.ChannelsTotal, 10)
+	data["channels_deleted"] = strconv.FormatInt(p.ChannelsDeleted, 10)
+	data["files_deleted"] = strconv.FormatInt(p.FilesDeleted, 10)
+	data["preferences_deleted"] = strconv.FormatInt(p.PreferencesDeleted, 10)
+}
+
+// TeamDeletionProgressFromJobData reads the progress from the data of a job, with any counts that are missing from it
+// being zero.
+func TeamDeletionProgressFromJobData(data map[string]string) *TeamDeletionProgress {
+	p := &TeamDeletionProgress{
+		TeamId: data["team_id"],
+		Stage:  data["stage"],
+	}
+	p.ChannelsTotal, _ = strconv.ParseInt(data["channels_total"], 10, 64)
+	p.ChannelsDeleted, _ = strconv.ParseInt(data["channels_deleted"], 10, 64)
+	p.FilesDeleted, _ = strconv.ParseInt(data["files_deleted"], 10, 64)
+	p.PreferencesDeleted, _ = strconv.ParseInt(data["preferences_deleted"], 10, 64)
+
+	return p
+}
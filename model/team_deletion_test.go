@@ -0,0 +1,43 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTeamDeletionProgressJobData(t *testing.T) {
+	p := &TeamDeletionProgress{
+		TeamId:             NewId(),
+		Stage:              TEAM_DELETION_STAGE_COMMANDS,
+		ChannelsTotal:      10,
+		ChannelsDeleted:    10,
+		FilesDeleted:       3,
+		PreferencesDeleted: 2,
+	}
+
+	data := map[string]string{"other": "value"}
+	p.ToJobData(data)
+	assert.Equal(t, "value", data["other"])
+	assert.Equal(t, p, TeamDeletionProgressFromJobData(data))
+
+	assert.Equal(t, &TeamDeletionProgress{TeamId: p.TeamId}, TeamDeletionProgressFromJobData(map[string]string{"team_id": p.TeamId}))
+}
+
+func TestTeamDeletionProgressPercent(t *testing.T) {
+	assert.Equal(t, int64(0), (&TeamDeletionProgress{}).Percent())
+	assert.Equal(t, int64(40), (&TeamDeletionProgress{Stage: TEAM_DELETION_STAGE_CHANNELS, ChannelsTotal: 4, ChannelsDeleted: 2}).Percent())
+	assert.Equal(t, int64(TEAM_DELETION_CHANNELS_PERCENT), (&TeamDeletionProgress{Stage: TEAM_DELETION_STAGE_CHANNELS}).Percent())
+	assert.Equal(t, int64(TEAM_DELETION_CHANNELS_PERCENT), (&TeamDeletionProgress{Stage: TEAM_DELETION_STAGE_WEBHOOKS}).Percent())
+	assert.Equal(t, int64(100), (&TeamDeletionProgress{Stage: TEAM_DELETION_STAGE_DONE}).Percent())
+
+	last := int64(0)
+	for _, stage := range TeamDeletionStages {
+		percent := (&TeamDeletionProgress{Stage: stage, ChannelsTotal: 1, ChannelsDeleted: 1}).Percent()
+		assert.True(t, percent >= last && percent < 100, stage)
+		last = percent
+	}
+}
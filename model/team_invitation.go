@@ -0,0 +1,154 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	TEAM_INVITATION_STATUS_QUEUED = "queued"
+	TEAM_INVITATION_STATUS_SENT   = "sent"
+	TEAM_INVITATION_STATUS_FAILED = "failed"
+
+	TEAM_INVITATION_CHANNELS_MAX_LENGTH = 2000
+	TEAM_INVITATION_REASON_MAX_RUNES    = 512
+
+	// HEADER_EMAIL_BOUNCE_SECRET is how the sender of a bounce notification proves that it's the email provider.
+	HEADER_EMAIL_BOUNCE_SECRET = "X-Mattermost-Bounce-Secret"
+)
+
+// TeamInvitation records an invitation that was emailed to someone to join a team, so that whoever sent it can see
+// whether it was sent or failed to be delivered. Reason explains why a failed invitation wasn't delivered.
+type TeamInvitation struct {
+	Id       string      `json:"id"`
+	TeamId   string      `json:"team_id"`
+	SenderId string      `json:"sender_id"`
+	Email    string      `json:"email"`
+	Role     string      `json:"role"`
+	Channels StringArray `json:"channels"`
+	Message  string      `json:"message"`
+	Status   string      `json:"status"`
+	Reason   string      `json:"reason"`
+	CreateAt int64       `json:"create_at"`
+	UpdateAt int64       `json:"update_at"`
+}
+
+// EmailBounce is a notification from an email provider that an email couldn't be delivered to Recipient.
+type EmailBounce struct {
+	Recipient string `json:"recipient"`
+	Reason    string `json:"reason"`
+}
+
+// NewTeamInvitation creates the record of an invitation that's about to be queued to be sent.
+func NewTeamInvitation(teamId string, senderId string, invite *Invite) *TeamInvitation {
+	return &TeamInvitation{
+		TeamId:   teamId,
+		SenderId: senderId,
+		Email:    invite.Email,
+		Role:     invite.Role,
+		Channels: invite.Channels,
+		Message:  invite.Message,
+		Status:   TEAM_INVITATION_STATUS_QUEUED,
+	}
+}
+
+func (i *TeamInvitation) PreSave() {
+	i.Id = NewId()
+	i.CreateAt = GetMillis()
+	i.UpdateAt = i.CreateAt
+
+	if i.Channels == nil {
+		i.Channels = StringArray{}
+	}
+}
+
+func (i *TeamInvitation) IsValid() *AppError {
+	if len(i.Id) != 26 {
+		return NewAppError("TeamInvitation.IsValid", "model.team_invitation.is_valid.id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if len(i.TeamId) != 26 {
+		return NewAppError("TeamInvitation.IsValid", "model.team_invitation.is_valid.team_id.app_error", nil, "id="+i.Id, http.StatusBadRequest)
+	}
+
+	if len(i.SenderId) != 26 {
+		return NewAppError("TeamInvitation.IsValid", "model.team_invitation.is_valid.sender_id.app_error", nil, "id="+i.Id, http.StatusBadRequest)
+	}
+
+	if err := i.ToInvite().IsValid(); err != nil {
+		return err
+	}
+
+	if len(ArrayToJson(i.Channels)) > TEAM_INVITATION_CHANNELS_MAX_LENGTH {
+		return NewAppError("TeamInvitation.IsValid", "model.team_invitation.is_valid.channels.app_error", nil, "id="+i.Id, http.StatusBadRequest)
+	}
+
+	if i.Status != TEAM_INVITATION_STATUS_QUEUED && i.Status != TEAM_INVITATION_STATUS_SENT && i.Status != TEAM_INVITATION_STATUS_FAILED {
+		return NewAppError("TeamInvitation.IsValid", "model.team_invitation.is_valid.status.app_error", nil, "id="+i.Id, http.StatusBadRequest)
+	}
+
+	if i.CreateAt == 0 {
+		return NewAppError("TeamInvitation.IsValid", "model.team_invitation.is_valid.create_at.app_error", nil, "id="+i.Id, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+// ToInvite returns the invite that the invitation was sent for.
+func (i *TeamInvitation) ToInvite() *Invite {
+	return &Invite{
+		Email:    i.Email,
+		Role:     i.Role,
+		Channels: i.Channels,
+		Message:  i.Message,
+	}
+}
+
+// TruncateTeamInvitationReason shortens the reason that an invitation failed to the most that's stored.
+func TruncateTeamInvitationReason(reason string) string {
+	reason = strings.TrimSpace(reason)
+	if utf8.RuneCountInString(reason) <= TEAM_INVITATION_REASON_MAX_RUNES {
+		return reason
+	}
+
+	return string([]rune(reason)[:TEAM_INVITATION_REASON_MAX_RUNES])
+}
+
+func (i *TeamInvitation) ToJson() string {
+	b, _ := json.Marshal(i)
+	return string(b)
+}
+
+func TeamInvitationFromJson(data io.Reader) *TeamInvitation {
+	var i *TeamInvitation
+	json.NewDecoder(data).Decode(&i)
+	return i
+}
+
+func TeamInvitationListToJson(i []*TeamInvitation) string {
+	b, _ := json.Marshal(i)
+	return string(b)
+}
+
+func TeamInvitationListFromJson(data io.Reader) []*TeamInvitation {
+	var i []*TeamInvitation
+	json.NewDecoder(data).Decode(&i)
+	return i
+}
+
+func (b *EmailBounce) ToJson() string {
+	j, _ := json.Marshal(b)
+	return string(j)
+}
+
+func EmailBounceFromJson(data io.Reader) *EmailBounce {
+	var b *EmailBounce
+	json.NewDecoder(data).Decode(&b)
+	return b
+}
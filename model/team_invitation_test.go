@@ -0,0 +1,55 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTeamInvitationIsValid(t *testing.T) {
+	invitation := NewTeamInvitation(NewId(), NewId(), &Invite{Email: "test@example.com", Role: INVITE_ROLE_MEMBER, Channels: []string{NewId()}})
+	invitation.PreSave()
+	require.Nil(t, invitation.IsValid())
+	assert.Equal(t, TEAM_INVITATION_STATUS_QUEUED, invitation.Status)
+
+	manyChannels := StringArray{}
+	for i := 0; i < 100; i++ {
+		manyChannels = append(manyChannels, NewId())
+	}
+
+	for id, change := range map[string]func(*TeamInvitation){
+		"model.team_invitation.is_valid.id.app_error":        func(i *TeamInvitation) { i.Id = "" },
+		"model.team_invitation.is_valid.team_id.app_error":   func(i *TeamInvitation) { i.TeamId = "" },
+		"model.team_invitation.is_valid.sender_id.app_error": func(i *TeamInvitation) { i.SenderId = "" },
+		"model.invite.is_valid.email.app_error":              func(i *TeamInvitation) { i.Email = "junk" },
+		"model.team_invitation.is_valid.channels.app_error":  func(i *TeamInvitation) { i.Channels = manyChannels },
+		"model.team_invitation.is_valid.status.app_error":    func(i *TeamInvitation) { i.Status = "bounced" },
+		"model.team_invitation.is_valid.create_at.app_error": func(i *TeamInvitation) { i.CreateAt = 0 },
+	} {
+		invalid := *invitation
+		change(&invalid)
+
+		err := invalid.IsValid()
+		if assert.NotNil(t, err, id) {
+			assert.Equal(t, id, err.Id)
+		}
+	}
+}
+
+func TestTruncateTeamInvitationReason(t *testing.T) {
+	assert.Equal(t, "mailbox full", TruncateTeamInvitationReason("  mailbox full\n"))
+
+	long := strings.Repeat("é", TEAM_INVITATION_REASON_MAX_RUNES+10)
+	assert.Equal(t, strings.Repeat("é", TEAM_INVITATION_REASON_MAX_RUNES), TruncateTeamInvitationReason(long))
+}
+
+func TestEmailBounceJson(t *testing.T) {
+	bounce := &EmailBounce{Recipient: "test@example.com", Reason: "550 mailbox unavailable"}
+	assert.Equal(t, bounce, EmailBounceFromJson(strings.NewReader(bounce.ToJson())))
+	assert.Nil(t, EmailBounceFromJson(strings.NewReader("junk")))
+}
@@ -24,3 +24,11 @@ func TeamStatsFromJson(data io.Reader) *TeamStats {
 	json.NewDecoder(data).Decode(&o)
 	return o
 }
+
+// TeamMemberCountsBatch is the result of checking the member counts of a batch of teams against their members.
+type TeamMemberCountsBatch struct {
+	// LastTeamId is the id of the last team in the batch, or empty if there were no teams left to check.
+	LastTeamId string
+	// Corrected is the number of teams in the batch whose counts had drifted and were fixed.
+	Corrected int64
+}
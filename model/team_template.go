@@ -0,0 +1,208 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	TEAM_TEMPLATE_NAME_MAX_LENGTH        = 64
+	TEAM_TEMPLATE_DESCRIPTION_MAX_LENGTH = 1024
+	TEAM_TEMPLATE_CONTENT_MAX_LENGTH     = 65535
+)
+
+// TeamTemplate describes a team to be created along with its channels, the channels that new members join, its
+// admins and its incoming webhooks, so that teams that are set up the same way each time can be created at once.
+// A template can be saved on the server to be listed and reused, in which case it has an Id and a Name of its own
+// and the name and display name of its team are usually given each time it's used.
+type TeamTemplate struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	CreatorId   string `json:"creator_id"`
+	CreateAt    int64  `json:"create_at"`
+	UpdateAt    int64  `json:"update_at"`
+
+	Team             *Team                          `json:"team" db:"-"`
+	Channels         []*TeamTemplateChannel         `json:"channels" db:"-"`
+	DefaultChannels  []string                       `json:"default_channels" db:"-"`
+	AdminEmails      []string                       `json:"admin_emails" db:"-"`
+	IncomingWebhooks []*TeamTemplateIncomingWebhook `json:"incoming_webhooks" db:"-"`
+
+	// Content is how the team, channels, default channels, admins and webhooks of a saved template are stored.
+	Content string `json:"-"`
+}
+
+type TeamTemplateChannel struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Type        string `json:"type"`
+	Header      string `json:"header"`
+	Purpose     string `json:"purpose"`
+}
+
+// TeamTemplateIncomingWebhook is an incoming webhook posting to the channel with ChannelName on the team.
+type TeamTemplateIncomingWebhook struct {
+	ChannelName string `json:"channel_name"`
+	DisplayName string `json:"display_name"`
+	Description string `json:"description"`
+	Username    string `json:"username"`
+	IconURL     string `json:"icon_url"`
+}
+
+// teamTemplateContent is what's stored in a saved template's Content.
+type teamTemplateContent struct {
+	Team             *Team                          `json:"team"`
+	Channels         []*TeamTemplateChannel         `json:"channels"`
+	DefaultChannels  []string                       `json:"default_channels"`
+	AdminEmails      []string                       `json:"admin_emails"`
+	IncomingWebhooks []*TeamTemplateIncomingWebhook `json:"incoming_webhooks"`
+}
+
+func (t *TeamTemplate) IsValid() *AppError {
+	if len(t.Id) != 26 {
+		return NewAppError("TeamTemplate.IsValid", "model.team_template.is_valid.id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if t.Name == "" || utf8.RuneCountInString(t.Name) > TEAM_TEMPLATE_NAME_MAX_LENGTH {
+		return NewAppError("TeamTemplate.IsValid", "model.team_template.is_valid.name.app_error", map[string]interface{}{"MaxLength": TEAM_TEMPLATE_NAME_MAX_LENGTH}, "id="+t.Id, http.StatusBadRequest)
+	}
+
+	if utf8.RuneCountInString(t.Description) > TEAM_TEMPLATE_DESCRIPTION_MAX_LENGTH {
+		return NewAppError("TeamTemplate.IsValid", "model.team_template.is_valid.description.app_error", map[string]interface{}{"MaxLength": TEAM_TEMPLATE_DESCRIPTION_MAX_LENGTH}, "id="+t.Id, http.StatusBadRequest)
+	}
+
+	if len(t.CreatorId) != 26 {
+		return NewAppError("TeamTemplate.IsValid", "model.team_template.is_valid.creator_id.app_error", nil, "id="+t.Id, http.StatusBadRequest)
+	}
+
+	if t.CreateAt == 0 {
+		return NewAppError("TeamTemplate.IsValid", "model.team_template.is_valid.create_at.app_error", nil, "id="+t.Id, http.StatusBadRequest)
+	}
+
+	if t.UpdateAt == 0 {
+		return NewAppError("TeamTemplate.IsValid", "model.team_template.is_valid.update_at.app_error", nil, "id="+t.Id, http.StatusBadRequest)
+	}
+
+	if len(t.Content) > TEAM_TEMPLATE_CONTENT_MAX_LENGTH {
+		return NewAppError("TeamTemplate.IsValid", "model.team_template.is_valid.content.app_error", map[string]interface{}{"MaxLength": TEAM_TEMPLATE_CONTENT_MAX_LENGTH}, "id="+t.Id, http.StatusBadRequest)
+	}
+
+	return t.IsValidContent()
+}
+
+// IsValidContent checks the channels, default channels, admins and webhooks of the template. Whether they can be
+// applied to a team, such as whether each admin has an account, is only known once the template is used.
+func (t *TeamTemplate) IsValidContent() *AppError {
+	channelNames := map[string]bool{}
+	for _, channel := range t.Channels {
+		if channel == nil || !IsValidChannelIdentifier(channel.Name) {
+			return NewAppError("TeamTemplate.IsValidContent", "model.team_template.is_valid.channel_name.app_error", nil, "", http.StatusBadRequest)
+		}
+
+		if channelNames[channel.Name] {
+			return NewAppError("TeamTemplate.IsValidContent", "model.team_template.is_valid.duplicate_channel.app_error", map[string]interface{}{"Name": channel.Name}, "", http.StatusBadRequest)
+		}
+		channelNames[channel.Name] = true
+
+		if channel.DisplayName == "" || utf8.RuneCountInString(channel.DisplayName) > CHANNEL_DISPLAY_NAME_MAX_RUNES {
+			return NewAppError("TeamTemplate.IsValidContent", "model.team_template.is_valid.channel_display_name.app_error", map[string]interface{}{"Name": channel.Name}, "", http.StatusBadRequest)
+		}
+
+		if !(channel.Type == CHANNEL_OPEN || channel.Type == CHANNEL_PRIVATE) {
+			return NewAppError("TeamTemplate.IsValidContent", "model.team_template.is_valid.channel_type.app_error", map[string]interface{}{"Name": channel.Name}, "", http.StatusBadRequest)
+		}
+
+		if utf8.RuneCountInString(channel.Header) > CHANNEL_HEADER_MAX_RUNES || utf8.RuneCountInString(channel.Purpose) > CHANNEL_PURPOSE_MAX_RUNES {
+			return NewAppError("TeamTemplate.IsValidContent", "model.team_template.is_valid.channel_header.app_error", map[string]interface{}{"Name": channel.Name}, "", http.StatusBadRequest)
+		}
+	}
+
+	for _, name := range t.DefaultChannels {
+		if !IsValidChannelIdentifier(name) {
+			return NewAppError("TeamTemplate.IsValidContent", "model.team_template.is_valid.channel_name.app_error", nil, "", http.StatusBadRequest)
+		}
+	}
+
+	for _, email := range t.AdminEmails {
+		if !IsValidEmail(email) {
+			return NewAppError("TeamTemplate.IsValidContent", "model.team_template.is_valid.admin_email.app_error", nil, "", http.StatusBadRequest)
+		}
+	}
+
+	for _, hook := range t.IncomingWebhooks {
+		if hook == nil || !IsValidChannelIdentifier(hook.ChannelName) {
+			return NewAppError("TeamTemplate.IsValidContent", "model.team_template.is_valid.channel_name.app_error", nil, "", http.StatusBadRequest)
+		}
+
+		if hook.DisplayName == "" {
+			return NewAppError("TeamTemplate.IsValidContent", "model.team_template.is_valid.webhook_display_name.app_error", nil, "", http.StatusBadRequest)
+		}
+	}
+
+	return nil
+}
+
+func (t *TeamTemplate) PreSave() {
+	if t.Id == "" {
+		t.Id = NewId()
+	}
+
+	t.CreateAt = GetMillis()
+	t.PreUpdate()
+}
+
+func (t *TeamTemplate) PreUpdate() {
+	t.UpdateAt = GetMillis()
+	t.Name = strings.TrimSpace(t.Name)
+
+	b, _ := json.Marshal(&teamTemplateContent{
+		Team:             t.Team,
+		Channels:         t.Channels,
+		DefaultChannels:  t.DefaultChannels,
+		AdminEmails:      t.AdminEmails,
+		IncomingWebhooks: t.IncomingWebhooks,
+	})
+	t.Content = string(b)
+}
+
+// LoadContent fills in the team, channels, default channels, admins and webhooks of a saved template from its
+// Content.
+func (t *TeamTemplate) LoadContent() {
+	var content teamTemplateContent
+	json.Unmarshal([]byte(t.Content), &content)
+
+	t.Team = content.Team
+	t.Channels = content.Channels
+	t.DefaultChannels = content.DefaultChannels
+	t.AdminEmails = content.AdminEmails
+	t.IncomingWebhooks = content.IncomingWebhooks
+}
+
+func (t *TeamTemplate) ToJson() string {
+	b, _ := json.Marshal(t)
+	return string(b)
+}
+
+func TeamTemplateFromJson(data io.Reader) *TeamTemplate {
+	var t *TeamTemplate
+	json.NewDecoder(data).Decode(&t)
+	return t
+}
+
+func TeamTemplateListToJson(t []*TeamTemplate) string {
+	b, _ := json.Marshal(t)
+	return string(b)
+}
+
+func TeamTemplateListFromJson(data io.Reader) []*TeamTemplate {
+	var t []*TeamTemplate
+	json.NewDecoder(data).Decode(&t)
+	return t
+}
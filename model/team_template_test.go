@@ -0,0 +1,105 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testTeamTemplate() *TeamTemplate {
+	return &TeamTemplate{
+		Name:        "Project team",
+		Description: "A team for each project",
+		CreatorId:   NewId(),
+		Team:        &Team{Type: TEAM_INVITE, Description: "Working on the project"},
+		Channels: []*TeamTemplateChannel{
+			{Name: "standup", DisplayName: "Standup", Type: CHANNEL_OPEN, Header: "Every day at 9"},
+			{Name: "leads", DisplayName: "Leads", Type: CHANNEL_PRIVATE, Purpose: "For the leads"},
+		},
+		DefaultChannels:  []string{"standup"},
+		AdminEmails:      []string{"lead@example.com"},
+		IncomingWebhooks: []*TeamTemplateIncomingWebhook{{ChannelName: "standup", DisplayName: "Reports", Username: "reporter"}},
+	}
+}
+
+func TestTeamTemplateJson(t *testing.T) {
+	template := testTeamTemplate()
+	template.PreSave()
+
+	rtemplate := TeamTemplateFromJson(strings.NewReader(template.ToJson()))
+	require.NotNil(t, rtemplate)
+	assert.Empty(t, rtemplate.Content)
+	rtemplate.Content = template.Content
+	assert.Equal(t, template, rtemplate)
+
+	templates := TeamTemplateListFromJson(strings.NewReader(TeamTemplateListToJson([]*TeamTemplate{template})))
+	require.Len(t, templates, 1)
+	assert.Equal(t, template.Id, templates[0].Id)
+}
+
+func TestTeamTemplateContent(t *testing.T) {
+	template := testTeamTemplate()
+	template.PreSave()
+	assert.NotEmpty(t, template.Content)
+
+	loaded := &TeamTemplate{Content: template.Content}
+	loaded.LoadContent()
+	assert.Equal(t, template.Team, loaded.Team)
+	assert.Equal(t, template.Channels, loaded.Channels)
+	assert.Equal(t, template.DefaultChannels, loaded.DefaultChannels)
+	assert.Equal(t, template.AdminEmails, loaded.AdminEmails)
+	assert.Equal(t, template.IncomingWebhooks, loaded.IncomingWebhooks)
+}
+
+func TestTeamTemplateIsValid(t *testing.T) {
+	template := testTeamTemplate()
+
+	err := template.IsValid()
+	require.NotNil(t, err)
+	assert.Equal(t, "model.team_template.is_valid.id.app_error", err.Id)
+
+	template.PreSave()
+	assert.Nil(t, template.IsValid())
+
+	for name, tc := range map[string]struct {
+		update func(template *TeamTemplate)
+		errId  string
+	}{
+		"no name": {func(template *TeamTemplate) { template.Name = "" }, "model.team_template.is_valid.name.app_error"},
+		"long description": {func(template *TeamTemplate) {
+			template.Description = strings.Repeat("a", TEAM_TEMPLATE_DESCRIPTION_MAX_LENGTH+1)
+		}, "model.team_template.is_valid.description.app_error"},
+		"no creator":        {func(template *TeamTemplate) { template.CreatorId = "" }, "model.team_template.is_valid.creator_id.app_error"},
+		"bad channel name":  {func(template *TeamTemplate) { template.Channels[0].Name = "Stand up" }, "model.team_template.is_valid.channel_name.app_error"},
+		"duplicate channel": {func(template *TeamTemplate) { template.Channels[1].Name = "standup" }, "model.team_template.is_valid.duplicate_channel.app_error"},
+		"no channel name":   {func(template *TeamTemplate) { template.Channels[0].DisplayName = "" }, "model.team_template.is_valid.channel_display_name.app_error"},
+		"direct channel":    {func(template *TeamTemplate) { template.Channels[0].Type = CHANNEL_DIRECT }, "model.team_template.is_valid.channel_type.app_error"},
+		"long header": {func(template *TeamTemplate) {
+			template.Channels[0].Header = strings.Repeat("a", CHANNEL_HEADER_MAX_RUNES+1)
+		}, "model.team_template.is_valid.channel_header.app_error"},
+		"bad default channel": {func(template *TeamTemplate) { template.DefaultChannels = []string{""} }, "model.team_template.is_valid.channel_name.app_error"},
+		"bad admin email":     {func(template *TeamTemplate) { template.AdminEmails = []string{"lead"} }, "model.team_template.is_valid.admin_email.app_error"},
+		"bad webhook channel": {func(template *TeamTemplate) { template.IncomingWebhooks[0].ChannelName = "" }, "model.team_template.is_valid.channel_name.app_error"},
+		"no webhook name":     {func(template *TeamTemplate) { template.IncomingWebhooks[0].DisplayName = "" }, "model.team_template.is_valid.webhook_display_name.app_error"},
+		"nil channel":         {func(template *TeamTemplate) { template.Channels = append(template.Channels, nil) }, "model.team_template.is_valid.channel_name.app_error"},
+		"content too large": {func(template *TeamTemplate) {
+			template.Content = strings.Repeat("a", TEAM_TEMPLATE_CONTENT_MAX_LENGTH+1)
+		}, "model.team_template.is_valid.content.app_error"},
+		"name too long to save": {func(template *TeamTemplate) { template.Name = strings.Repeat("a", TEAM_TEMPLATE_NAME_MAX_LENGTH+1) }, "model.team_template.is_valid.name.app_error"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			template := testTeamTemplate()
+			template.PreSave()
+			tc.update(template)
+
+			err := template.IsValid()
+			require.NotNil(t, err)
+			assert.Equal(t, tc.errId, err.Id)
+		})
+	}
+}
@@ -62,6 +62,21 @@ func TestTeamIsValid(t *testing.T) {
 	if err := o.IsValid(); err != nil {
 		t.Fatal(err)
 	}
+
+	o.WelcomeMessage = strings.Repeat("0", TEAM_WELCOME_MESSAGE_MAX_RUNES+1)
+	if err := o.IsValid(); err == nil {
+		t.Fatal("should be invalid")
+	}
+
+	o.WelcomeMessage = "Welcome {{.Username}}"
+	if err := o.IsValid(); err == nil {
+		t.Fatal("should be invalid")
+	}
+
+	o.WelcomeMessage = "Welcome to {{.TeamName}}, {{.UserFirstName}}"
+	if err := o.IsValid(); err != nil {
+		t.Fatal(err)
+	}
 }
 
 func TestTeamPreSave(t *testing.T) {
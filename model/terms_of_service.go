@@ -0,0 +1,105 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"unicode/utf8"
+)
+
+const (
+	TERMS_OF_SERVICE_TEXT_MAX_RUNES = 16383
+
+	// HEADER_TERMS_OF_SERVICE_REQUIRED is set on responses to users who still have to accept the latest
+	// terms of service.
+	HEADER_TERMS_OF_SERVICE_REQUIRED = "X-Terms-Of-Service-Required"
+)
+
+// TermsOfService is one version of the custom terms of service text. Creating a new version requires
+// every user to accept it again.
+type TermsOfService struct {
+	Id       string `json:"id"`
+	CreateAt int64  `json:"create_at"`
+	UserId   string `json:"user_id"`
+	Text     string `json:"text"`
+}
+
+// UserTermsOfService records the version of the terms of service that a user last accepted.
+type UserTermsOfService struct {
+	UserId           string `json:"user_id"`
+	TermsOfServiceId string `json:"terms_of_service_id"`
+	CreateAt         int64  `json:"create_at"`
+}
+
+func (o *TermsOfService) IsValid() *AppError {
+	if len(o.Id) != 26 {
+		return NewAppError("TermsOfService.IsValid", "model.terms_of_service.is_valid.id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if o.CreateAt == 0 {
+		return NewAppError("TermsOfService.IsValid", "model.terms_of_service.is_valid.create_at.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	if len(o.UserId) != 26 {
+		return NewAppError("TermsOfService.IsValid", "model.terms_of_service.is_valid.user_id.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	if o.Text == "" || utf8.RuneCountInString(o.Text) > TERMS_OF_SERVICE_TEXT_MAX_RUNES {
+		return NewAppError("TermsOfService.IsValid", "model.terms_of_service.is_valid.text.app_error", map[string]interface{}{"MaxLength": TERMS_OF_SERVICE_TEXT_MAX_RUNES}, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (o *TermsOfService) PreSave() {
+	if o.Id == "" {
+		o.Id = NewId()
+	}
+
+	o.CreateAt = GetMillis()
+}
+
+func (o *TermsOfService) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func TermsOfServiceFromJson(data io.Reader) *TermsOfService {
+	var o *TermsOfService
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
+
+func (o *UserTermsOfService) IsValid() *AppError {
+	if len(o.UserId) != 26 {
+		return NewAppError("UserTermsOfService.IsValid", "model.user_terms_of_service.is_valid.user_id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if len(o.TermsOfServiceId) != 26 {
+		return NewAppError("UserTermsOfService.IsValid", "model.user_terms_of_service.is_valid.terms_of_service_id.app_error", nil, "user_id="+o.UserId, http.StatusBadRequest)
+	}
+
+	if o.CreateAt == 0 {
+		return NewAppError("UserTermsOfService.IsValid", "model.user_terms_of_service.is_valid.create_at.app_error", nil, "user_id="+o.UserId, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (o *UserTermsOfService) PreSave() {
+	o.CreateAt = GetMillis()
+}
+
+func (o *UserTermsOfService) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func UserTermsOfServiceFromJson(data io.Reader) *UserTermsOfService {
+	var o *UserTermsOfService
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
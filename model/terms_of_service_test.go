@@ -0,0 +1,52 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTermsOfServiceIsValid(t *testing.T) {
+	terms := &TermsOfService{
+		UserId: NewId(),
+		Text:   "You must behave",
+	}
+	assert.NotNil(t, terms.IsValid())
+
+	terms.PreSave()
+	assert.Nil(t, terms.IsValid())
+
+	terms.Text = ""
+	assert.NotNil(t, terms.IsValid())
+
+	terms.Text = strings.Repeat("a", TERMS_OF_SERVICE_TEXT_MAX_RUNES+1)
+	assert.NotNil(t, terms.IsValid())
+
+	terms.Text = "You must behave"
+	terms.UserId = ""
+	assert.NotNil(t, terms.IsValid())
+}
+
+func TestTermsOfServiceJson(t *testing.T) {
+	terms := &TermsOfService{Id: NewId(), Text: "You must behave"}
+	result := TermsOfServiceFromJson(strings.NewReader(terms.ToJson()))
+	assert.Equal(t, terms, result)
+}
+
+func TestUserTermsOfServiceIsValid(t *testing.T) {
+	accepted := &UserTermsOfService{
+		UserId:           NewId(),
+		TermsOfServiceId: NewId(),
+	}
+	assert.NotNil(t, accepted.IsValid())
+
+	accepted.PreSave()
+	assert.Nil(t, accepted.IsValid())
+
+	accepted.TermsOfServiceId = ""
+	assert.NotNil(t, accepted.IsValid())
+}
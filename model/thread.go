@@ -0,0 +1,120 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// Thread tracks the replies made to a root post.
+type Thread struct {
+	PostId       string      `json:"id"`
+	ChannelId    string      `json:"channel_id"`
+	ReplyCount   int64       `json:"reply_count"`
+	LastReplyAt  int64       `json:"last_reply_at"`
+	Participants StringArray `json:"participants"`
+}
+
+// ThreadMembership tracks whether a user is following a thread and how much of it they've read.
+type ThreadMembership struct {
+	PostId         string `json:"post_id"`
+	UserId         string `json:"user_id"`
+	Following      bool   `json:"following"`
+	LastViewed     int64  `json:"last_viewed"`
+	LastUpdated    int64  `json:"last_updated"`
+	UnreadMentions int64  `json:"unread_mentions"`
+}
+
+// ThreadResponse is a thread as seen by a single user.
+type ThreadResponse struct {
+	PostId         string      `json:"id"`
+	ChannelId      string      `json:"channel_id"`
+	ReplyCount     int64       `json:"reply_count"`
+	LastReplyAt    int64       `json:"last_reply_at"`
+	LastViewedAt   int64       `json:"last_viewed_at"`
+	Participants   StringArray `json:"participants"`
+	UnreadReplies  int64       `json:"unread_replies"`
+	UnreadMentions int64       `json:"unread_mentions"`
+	Post           *Post       `json:"post" db:"-"`
+}
+
+type Threads struct {
+	Total               int64             `json:"total"`
+	TotalUnreadThreads  int64             `json:"total_unread_threads"`
+	TotalUnreadMentions int64             `json:"total_unread_mentions"`
+	Threads             []*ThreadResponse `json:"threads"`
+}
+
+func (o *Thread) IsValid() *AppError {
+	if len(o.PostId) != 26 {
+		return NewAppError("Thread.IsValid", "model.thread.is_valid.post_id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if len(o.ChannelId) != 26 {
+		return NewAppError("Thread.IsValid", "model.thread.is_valid.channel_id.app_error", nil, "post_id="+o.PostId, http.StatusBadRequest)
+	}
+
+	if o.ReplyCount < 0 {
+		return NewAppError("Thread.IsValid", "model.thread.is_valid.reply_count.app_error", nil, "post_id="+o.PostId, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (o *Thread) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+// HasParticipant returns true if the given user has posted in the thread.
+func (o *Thread) HasParticipant(userId string) bool {
+	for _, participant := range o.Participants {
+		if participant == userId {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (o *ThreadMembership) IsValid() *AppError {
+	if len(o.PostId) != 26 {
+		return NewAppError("ThreadMembership.IsValid", "model.thread_membership.is_valid.post_id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if len(o.UserId) != 26 {
+		return NewAppError("ThreadMembership.IsValid", "model.thread_membership.is_valid.user_id.app_error", nil, "post_id="+o.PostId, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (o *ThreadMembership) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func (o *ThreadResponse) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func ThreadResponseFromJson(data io.Reader) *ThreadResponse {
+	var o *ThreadResponse
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
+
+func (o *Threads) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func ThreadsFromJson(data io.Reader) *Threads {
+	var o *Threads
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
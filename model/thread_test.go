@@ -0,0 +1,81 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThreadIsValid(t *testing.T) {
+	thread := Thread{
+		PostId:    NewId(),
+		ChannelId: NewId(),
+	}
+	assert.Nil(t, thread.IsValid())
+
+	thread.ReplyCount = -1
+	assert.NotNil(t, thread.IsValid())
+
+	thread.ReplyCount = 1
+	thread.ChannelId = "junk"
+	assert.NotNil(t, thread.IsValid())
+
+	thread.ChannelId = NewId()
+	thread.PostId = ""
+	assert.NotNil(t, thread.IsValid())
+}
+
+func TestThreadHasParticipant(t *testing.T) {
+	userId := NewId()
+	thread := Thread{Participants: StringArray{NewId(), userId}}
+
+	assert.True(t, thread.HasParticipant(userId))
+	assert.False(t, thread.HasParticipant(NewId()))
+}
+
+func TestThreadMembershipIsValid(t *testing.T) {
+	membership := ThreadMembership{
+		PostId: NewId(),
+		UserId: NewId(),
+	}
+	assert.Nil(t, membership.IsValid())
+
+	membership.UserId = "junk"
+	assert.NotNil(t, membership.IsValid())
+
+	membership.UserId = NewId()
+	membership.PostId = "junk"
+	assert.NotNil(t, membership.IsValid())
+}
+
+func TestThreadsJson(t *testing.T) {
+	threads := &Threads{
+		Total:               1,
+		TotalUnreadThreads:  1,
+		TotalUnreadMentions: 2,
+		Threads: []*ThreadResponse{
+			{
+				PostId:         NewId(),
+				ChannelId:      NewId(),
+				ReplyCount:     3,
+				Participants:   StringArray{NewId()},
+				UnreadReplies:  1,
+				UnreadMentions: 2,
+			},
+		},
+	}
+
+	decoded := ThreadsFromJson(strings.NewReader(threads.ToJson()))
+	require.NotNil(t, decoded)
+	assert.Equal(t, threads, decoded)
+
+	thread := ThreadResponseFromJson(strings.NewReader(threads.Threads[0].ToJson()))
+	assert.Equal(t, threads.Threads[0], thread)
+
+	assert.Nil(t, ThreadsFromJson(strings.NewReader("junk")))
+}
@@ -626,3 +626,13 @@ var DefaultSupportedTimezones = []string{
 	"WET",
 	"Zulu",
 }
+
+// GetPreferredTimezone returns the name of the timezone that the user has chosen, which is either the one detected
+// by their client or one that they set themselves. It's blank if the one they chose hasn't been set.
+func (u *User) GetPreferredTimezone() string {
+	if u.Timezone["useAutomaticTimezone"] == "true" {
+		return u.Timezone["automaticTimezone"]
+	}
+
+	return u.Timezone["manualTimezone"]
+}
@@ -7,6 +7,7 @@ import "net/http"
 
 const (
 	TOKEN_SIZE            = 64
+	TOKEN_EXTRA_MAX_SIZE  = 2048
 	MAX_TOKEN_EXIPRY_TIME = 1000 * 60 * 60 * 24 // 24 hour
 	TOKEN_TYPE_OAUTH      = "oauth"
 )
@@ -36,5 +37,9 @@ func (t *Token) IsValid() *AppError {
 		return NewAppError("Token.IsValid", "model.token.is_valid.expiry", nil, "", http.StatusInternalServerError)
 	}
 
+	if len(t.Extra) > TOKEN_EXTRA_MAX_SIZE {
+		return NewAppError("Token.IsValid", "model.token.is_valid.extra", nil, "", http.StatusInternalServerError)
+	}
+
 	return nil
 }
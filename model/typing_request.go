@@ -0,0 +1,26 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// TypingRequest says that a user is typing in a channel, or in a thread if ParentId is set.
+type TypingRequest struct {
+	ChannelId string `json:"channel_id"`
+	ParentId  string `json:"parent_id"`
+}
+
+func (o *TypingRequest) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func TypingRequestFromJson(data io.Reader) *TypingRequest {
+	var o *TypingRequest
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"regexp"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 
 	"golang.org/x/crypto/bcrypt"
@@ -36,15 +37,18 @@ const (
 	DEFAULT_LOCALE          = "en"
 	USER_AUTH_SERVICE_EMAIL = "email"
 
-	USER_EMAIL_MAX_LENGTH     = 128
-	USER_NICKNAME_MAX_RUNES   = 64
-	USER_POSITION_MAX_RUNES   = 128
-	USER_FIRST_NAME_MAX_RUNES = 64
-	USER_LAST_NAME_MAX_RUNES  = 64
-	USER_AUTH_DATA_MAX_LENGTH = 128
-	USER_NAME_MAX_LENGTH      = 64
-	USER_NAME_MIN_LENGTH      = 1
-	USER_PASSWORD_MAX_LENGTH  = 72
+	USER_EMAIL_MAX_LENGTH        = 128
+	USER_NICKNAME_MAX_RUNES      = 64
+	USER_POSITION_MAX_RUNES      = 128
+	USER_FIRST_NAME_MAX_RUNES    = 64
+	USER_LAST_NAME_MAX_RUNES     = 64
+	USER_AUTH_DATA_MAX_LENGTH    = 128
+	USER_AUTH_SERVICE_MAX_LENGTH = 32
+	USER_NAME_MAX_LENGTH         = 64
+	USER_NAME_MIN_LENGTH         = 1
+	USER_PASSWORD_MAX_LENGTH     = 72
+
+	SYSTEM_BOT_USERNAME = "system-bot"
 )
 
 type User struct {
@@ -73,7 +77,12 @@ type User struct {
 	Timezone           StringMap `json:"timezone"`
 	MfaActive          bool      `json:"mfa_active,omitempty"`
 	MfaSecret          string    `json:"mfa_secret,omitempty"`
+	DeactivateAt       int64     `json:"deactivate_at,omitempty"`
+	IsBot              bool      `json:"is_bot,omitempty"`
+	LastSeenAt         int64     `json:"last_seen_at,omitempty"`
 	LastActivityAt     int64     `db:"-" json:"last_activity_at,omitempty"`
+	Attributes         StringMap `db:"-" json:"attributes,omitempty"`
+	IsBlocked          bool      `db:"-" json:"is_blocked,omitempty"`
 }
 
 type UserPatch struct {
@@ -109,6 +118,9 @@ func (u *User) DeepCopy() *User {
 	if u.Timezone != nil {
 		copyUser.Timezone = CopyStringMap(u.Timezone)
 	}
+	if u.Attributes != nil {
+		copyUser.Attributes = CopyStringMap(u.Attributes)
+	}
 	return &copyUser
 }
 
@@ -248,15 +260,14 @@ func (u *User) PreUpdate() {
 	if u.NotifyProps == nil || len(u.NotifyProps) == 0 {
 		u.SetDefaultNotifications()
 	} else if _, ok := u.NotifyProps["mention_keys"]; ok {
-		// Remove any blank mention keys
-		splitKeys := strings.Split(u.NotifyProps["mention_keys"], ",")
-		goodKeys := []string{}
-		for _, key := range splitKeys {
-			if len(key) > 0 {
-				goodKeys = append(goodKeys, strings.ToLower(key))
+		// Remove any blank mention keys and lower case the ones that aren't case sensitive
+		keys := ParseMentionKeys(u.NotifyProps["mention_keys"])
+		for i, key := range keys {
+			if !key.CaseSensitive {
+				keys[i].Key = strings.ToLower(key.Key)
 			}
 		}
-		u.NotifyProps["mention_keys"] = strings.Join(goodKeys, ",")
+		u.NotifyProps["mention_keys"] = MentionKeysToString(keys)
 	}
 }
 
@@ -274,18 +285,14 @@ func (u *User) SetDefaultNotifications() {
 }
 
 func (user *User) UpdateMentionKeysFromUsername(oldUsername string) {
-	nonUsernameKeys := []string{}
-	splitKeys := strings.Split(user.NotifyProps["mention_keys"], ",")
-	for _, key := range splitKeys {
-		if key != oldUsername && key != "@"+oldUsername {
-			nonUsernameKeys = append(nonUsernameKeys, key)
+	keys := []MentionKey{{Key: user.Username}, {Key: "@" + user.Username}}
+	for _, key := range ParseMentionKeys(user.NotifyProps["mention_keys"]) {
+		if key.Key != oldUsername && key.Key != "@"+oldUsername {
+			keys = append(keys, key)
 		}
 	}
 
-	user.NotifyProps["mention_keys"] = user.Username + ",@" + user.Username
-	if len(nonUsernameKeys) > 0 {
-		user.NotifyProps["mention_keys"] += "," + strings.Join(nonUsernameKeys, ",")
-	}
+	user.NotifyProps["mention_keys"] = MentionKeysToString(keys)
 }
 
 func (u *User) Patch(patch *UserPatch) {
@@ -336,6 +343,16 @@ func (u *User) ToJson() string {
 	return string(b)
 }
 
+// MarshalJSON adds an is_guest flag to the encoded user so that clients can tag guest accounts
+// without having to inspect their roles.
+func (u User) MarshalJSON() ([]byte, error) {
+	type user User
+	return json.Marshal(struct {
+		user
+		IsGuest bool `json:"is_guest,omitempty"`
+	}{user(u), u.IsGuest()})
+}
+
 func (u *UserPatch) ToJson() string {
 	b, _ := json.Marshal(u)
 	return string(b)
@@ -370,6 +387,12 @@ func (u *User) Sanitize(options map[string]bool) {
 	if len(options) != 0 && !options["authservice"] {
 		u.AuthService = ""
 	}
+	if len(options) != 0 && !options["deactivateat"] {
+		u.DeactivateAt = 0
+	}
+	if len(options) != 0 && !options["lastseenat"] {
+		u.LastSeenAt = 0
+	}
 }
 
 func (u *User) ClearNonProfileFields() {
@@ -435,6 +458,24 @@ func (u *User) GetDisplayName(nameFormat string) string {
 	return displayName
 }
 
+// GetInitials returns the initials of the user's display name in the given format, which are the first letters of its
+// first and last words, such as "JD" for "John Doe" or "john.doe".
+func (u *User) GetInitials(nameFormat string) string {
+	words := strings.FieldsFunc(u.GetDisplayName(nameFormat), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return ""
+	}
+
+	initials := []rune{[]rune(words[0])[0]}
+	if len(words) > 1 {
+		initials = append(initials, []rune(words[len(words)-1])[0])
+	}
+
+	return strings.ToUpper(string(initials))
+}
+
 func (u *User) GetRoles() []string {
 	return strings.Fields(u.Roles)
 }
@@ -489,6 +530,10 @@ func (u *User) IsOAuthUser() bool {
 	return u.AuthService == USER_AUTH_SERVICE_GITLAB
 }
 
+func (u *User) IsGuest() bool {
+	return IsInRole(u.Roles, SYSTEM_GUEST_ROLE_ID)
+}
+
 func (u *User) IsLDAPUser() bool {
 	return u.AuthService == USER_AUTH_SERVICE_LDAP
 }
@@ -532,6 +577,77 @@ func UserListToJson(u []*User) string {
 	return string(b)
 }
 
+// USER_SPARSE_FIELDS are the fields of a user that clients can ask for on their own with the fields query parameter.
+var USER_SPARSE_FIELDS = map[string]bool{
+	"id": true, "create_at": true, "update_at": true, "delete_at": true, "username": true, "email": true,
+	"nickname": true, "first_name": true, "last_name": true, "position": true, "roles": true, "locale": true,
+	"last_picture_update": true, "is_bot": true,
+}
+
+// ToSparseMap returns only the given fields of the user for serializing. Fields that aren't in USER_SPARSE_FIELDS are
+// left out, and fields that are empty are left out where they would be by ToJson.
+func (u *User) ToSparseMap(fields []string) map[string]interface{} {
+	m := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		switch field {
+		case "id":
+			m[field] = u.Id
+		case "create_at":
+			if u.CreateAt != 0 {
+				m[field] = u.CreateAt
+			}
+		case "update_at":
+			if u.UpdateAt != 0 {
+				m[field] = u.UpdateAt
+			}
+		case "delete_at":
+			m[field] = u.DeleteAt
+		case "username":
+			m[field] = u.Username
+		case "email":
+			m[field] = u.Email
+		case "nickname":
+			m[field] = u.Nickname
+		case "first_name":
+			m[field] = u.FirstName
+		case "last_name":
+			m[field] = u.LastName
+		case "position":
+			m[field] = u.Position
+		case "roles":
+			m[field] = u.Roles
+		case "locale":
+			m[field] = u.Locale
+		case "last_picture_update":
+			if u.LastPictureUpdate != 0 {
+				m[field] = u.LastPictureUpdate
+			}
+		case "is_bot":
+			if u.IsBot {
+				m[field] = u.IsBot
+			}
+		}
+	}
+
+	return m
+}
+
+// UserListToSparseJson serializes only the given fields of each user, or all of them if no fields are given. The users
+// must already have been sanitized.
+func UserListToSparseJson(u []*User, fields []string) string {
+	if len(fields) == 0 {
+		return UserListToJson(u)
+	}
+
+	sparse := make([]map[string]interface{}, len(u))
+	for i, user := range u {
+		sparse[i] = user.ToSparseMap(fields)
+	}
+
+	b, _ := json.Marshal(sparse)
+	return string(b)
+}
+
 func UserListFromJson(data io.Reader) []*User {
 	var users []*User
 	json.NewDecoder(data).Decode(&users)
@@ -0,0 +1,243 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	USER_ATTRIBUTE_TYPE_TEXT   = "text"
+	USER_ATTRIBUTE_TYPE_SELECT = "select"
+	USER_ATTRIBUTE_TYPE_DATE   = "date"
+
+	// Private attributes can only be seen by the user that they belong to and by system admins.
+	USER_ATTRIBUTE_VISIBILITY_PUBLIC  = "public"
+	USER_ATTRIBUTE_VISIBILITY_PRIVATE = "private"
+
+	USER_ATTRIBUTE_NAME_MAX_RUNES     = 64
+	USER_ATTRIBUTE_VALUE_MAX_RUNES    = 256
+	USER_ATTRIBUTE_OPTIONS_MAX_LENGTH = 4000
+	USER_ATTRIBUTE_DATE_FORMAT        = "2006-01-02"
+)
+
+// UserAttributeField is an admin-defined field that can be filled in on every user's profile, such as their
+// department or office. Read only fields are meant for values that are synced from LDAP or SAML, so they can only be
+// set by system admins.
+type UserAttributeField struct {
+	Id         string      `json:"id"`
+	CreateAt   int64       `json:"create_at"`
+	UpdateAt   int64       `json:"update_at"`
+	Name       string      `json:"name"`
+	Type       string      `json:"type"`
+	Options    StringArray `json:"options"`
+	Visibility string      `json:"visibility"`
+	SortOrder  int64       `json:"sort_order"`
+	ReadOnly   bool        `json:"read_only"`
+}
+
+type UserAttributeFieldPatch struct {
+	Name       *string      `json:"name"`
+	Type       *string      `json:"type"`
+	Options    *StringArray `json:"options"`
+	Visibility *string      `json:"visibility"`
+	SortOrder  *int64       `json:"sort_order"`
+	ReadOnly   *bool        `json:"read_only"`
+}
+
+// UserAttribute is the value of a UserAttributeField for a single user.
+type UserAttribute struct {
+	UserId   string `json:"user_id"`
+	FieldId  string `json:"field_id"`
+	Value    string `json:"value"`
+	UpdateAt int64  `json:"update_at"`
+}
+
+func (o *UserAttributeField) IsValid() *AppError {
+	if len(o.Id) != 26 {
+		return NewAppError("UserAttributeField.IsValid", "model.user_attribute_field.is_valid.id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if o.CreateAt == 0 {
+		return NewAppError("UserAttributeField.IsValid", "model.user_attribute_field.is_valid.create_at.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	if o.UpdateAt == 0 {
+		return NewAppError("UserAttributeField.IsValid", "model.user_attribute_field.is_valid.update_at.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	if o.Name == "" || utf8.RuneCountInString(o.Name) > USER_ATTRIBUTE_NAME_MAX_RUNES {
+		return NewAppError("UserAttributeField.IsValid", "model.user_attribute_field.is_valid.name.app_error", map[string]interface{}{"Max": USER_ATTRIBUTE_NAME_MAX_RUNES}, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	switch o.Type {
+	case USER_ATTRIBUTE_TYPE_TEXT, USER_ATTRIBUTE_TYPE_DATE:
+		if len(o.Options) != 0 {
+			return NewAppError("UserAttributeField.IsValid", "model.user_attribute_field.is_valid.options.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+		}
+	case USER_ATTRIBUTE_TYPE_SELECT:
+		if len(o.Options) == 0 || len(ArrayToJson(o.Options)) > USER_ATTRIBUTE_OPTIONS_MAX_LENGTH {
+			return NewAppError("UserAttributeField.IsValid", "model.user_attribute_field.is_valid.options.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+		}
+
+		seen := make(map[string]bool, len(o.Options))
+		for _, option := range o.Options {
+			if option == "" || utf8.RuneCountInString(option) > USER_ATTRIBUTE_VALUE_MAX_RUNES || seen[option] {
+				return NewAppError("UserAttributeField.IsValid", "model.user_attribute_field.is_valid.options.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+			}
+			seen[option] = true
+		}
+	default:
+		return NewAppError("UserAttributeField.IsValid", "model.user_attribute_field.is_valid.type.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	if o.Visibility != USER_ATTRIBUTE_VISIBILITY_PUBLIC && o.Visibility != USER_ATTRIBUTE_VISIBILITY_PRIVATE {
+		return NewAppError("UserAttributeField.IsValid", "model.user_attribute_field.is_valid.visibility.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (o *UserAttributeField) PreSave() {
+	if o.Id == "" {
+		o.Id = NewId()
+	}
+
+	if o.Visibility == "" {
+		o.Visibility = USER_ATTRIBUTE_VISIBILITY_PUBLIC
+	}
+
+	if o.Options == nil {
+		o.Options = StringArray{}
+	}
+
+	o.Name = strings.TrimSpace(o.Name)
+
+	o.CreateAt = GetMillis()
+	o.UpdateAt = o.CreateAt
+}
+
+func (o *UserAttributeField) PreUpdate() {
+	if o.Options == nil {
+		o.Options = StringArray{}
+	}
+
+	o.Name = strings.TrimSpace(o.Name)
+
+	o.UpdateAt = GetMillis()
+}
+
+func (o *UserAttributeField) Patch(patch *UserAttributeFieldPatch) {
+	if patch.Name != nil {
+		o.Name = *patch.Name
+	}
+
+	if patch.Type != nil {
+		o.Type = *patch.Type
+	}
+
+	if patch.Options != nil {
+		o.Options = *patch.Options
+	}
+
+	if patch.Visibility != nil {
+		o.Visibility = *patch.Visibility
+	}
+
+	if patch.SortOrder != nil {
+		o.SortOrder = *patch.SortOrder
+	}
+
+	if patch.ReadOnly != nil {
+		o.ReadOnly = *patch.ReadOnly
+	}
+}
+
+// IsValidValue returns an error if the value can't be stored for this field. Select fields only accept one of
+// their options, and date fields only accept dates formatted like 2006-01-02.
+func (o *UserAttributeField) IsValidValue(value string) *AppError {
+	if value == "" || utf8.RuneCountInString(value) > USER_ATTRIBUTE_VALUE_MAX_RUNES {
+		return NewAppError("UserAttributeField.IsValidValue", "model.user_attribute_field.is_valid_value.length.app_error", map[string]interface{}{"Name": o.Name, "Max": USER_ATTRIBUTE_VALUE_MAX_RUNES}, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	switch o.Type {
+	case USER_ATTRIBUTE_TYPE_SELECT:
+		for _, option := range o.Options {
+			if option == value {
+				return nil
+			}
+		}
+
+		return NewAppError("UserAttributeField.IsValidValue", "model.user_attribute_field.is_valid_value.option.app_error", map[string]interface{}{"Name": o.Name}, "id="+o.Id, http.StatusBadRequest)
+	case USER_ATTRIBUTE_TYPE_DATE:
+		if _, err := time.Parse(USER_ATTRIBUTE_DATE_FORMAT, value); err != nil {
+			return NewAppError("UserAttributeField.IsValidValue", "model.user_attribute_field.is_valid_value.date.app_error", map[string]interface{}{"Name": o.Name}, "id="+o.Id, http.StatusBadRequest)
+		}
+	}
+
+	return nil
+}
+
+// IsVisibleTo returns true if a user's value for this field can be seen by someone other than that user.
+func (o *UserAttributeField) IsVisibleTo(asAdmin bool) bool {
+	return asAdmin || o.Visibility == USER_ATTRIBUTE_VISIBILITY_PUBLIC
+}
+
+func (o *UserAttributeField) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func UserAttributeFieldFromJson(data io.Reader) *UserAttributeField {
+	var o *UserAttributeField
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
+
+func UserAttributeFieldListToJson(fields []*UserAttributeField) string {
+	b, _ := json.Marshal(fields)
+	return string(b)
+}
+
+func UserAttributeFieldListFromJson(data io.Reader) []*UserAttributeField {
+	var o []*UserAttributeField
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
+
+func (o *UserAttributeFieldPatch) ToJson() string {
+	b, _ := json.Marshal(o)
+	return string(b)
+}
+
+func UserAttributeFieldPatchFromJson(data io.Reader) *UserAttributeFieldPatch {
+	var o *UserAttributeFieldPatch
+	json.NewDecoder(data).Decode(&o)
+	return o
+}
+
+func (o *UserAttribute) IsValid() *AppError {
+	if len(o.UserId) != 26 {
+		return NewAppError("UserAttribute.IsValid", "model.user_attribute.is_valid.user_id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if len(o.FieldId) != 26 {
+		return NewAppError("UserAttribute.IsValid", "model.user_attribute.is_valid.field_id.app_error", nil, "user_id="+o.UserId, http.StatusBadRequest)
+	}
+
+	if o.Value == "" || utf8.RuneCountInString(o.Value) > USER_ATTRIBUTE_VALUE_MAX_RUNES {
+		return NewAppError("UserAttribute.IsValid", "model.user_attribute.is_valid.value.app_error", nil, "user_id="+o.UserId+", field_id="+o.FieldId, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (o *UserAttribute) PreSave() {
+	o.UpdateAt = GetMillis()
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserAttributeFieldIsValid(t *testing.T) {
+	field := UserAttributeField{
+		Name: " Department ",
+		Type: USER_ATTRIBUTE_TYPE_TEXT,
+	}
+	field.PreSave()
+
+	assert.Equal(t, "Department", field.Name)
+	assert.Equal(t, USER_ATTRIBUTE_VISIBILITY_PUBLIC, field.Visibility)
+	assert.Nil(t, field.IsValid())
+
+	field.Name = ""
+	assert.NotNil(t, field.IsValid())
+
+	field.Name = strings.Repeat("ü", USER_ATTRIBUTE_NAME_MAX_RUNES+1)
+	assert.NotNil(t, field.IsValid())
+
+	field.Name = "Department"
+	field.Visibility = "secret"
+	assert.NotNil(t, field.IsValid())
+
+	field.Visibility = USER_ATTRIBUTE_VISIBILITY_PRIVATE
+	field.Type = "number"
+	assert.NotNil(t, field.IsValid())
+
+	field.Type = USER_ATTRIBUTE_TYPE_DATE
+	field.Options = StringArray{"Engineering"}
+	assert.NotNil(t, field.IsValid(), "only select fields can have options")
+
+	field.Type = USER_ATTRIBUTE_TYPE_SELECT
+	assert.Nil(t, field.IsValid())
+
+	for _, options := range []StringArray{{}, {""}, {"Sales", "Sales"}, {strings.Repeat("a", USER_ATTRIBUTE_VALUE_MAX_RUNES+1)}} {
+		field.Options = options
+		assert.NotNil(t, field.IsValid(), options)
+	}
+}
+
+func TestUserAttributeFieldIsValidValue(t *testing.T) {
+	text := UserAttributeField{Type: USER_ATTRIBUTE_TYPE_TEXT}
+	assert.Nil(t, text.IsValidValue("E12345"))
+	assert.NotNil(t, text.IsValidValue(""))
+	assert.NotNil(t, text.IsValidValue(strings.Repeat("ü", USER_ATTRIBUTE_VALUE_MAX_RUNES+1)))
+
+	list := UserAttributeField{Type: USER_ATTRIBUTE_TYPE_SELECT, Options: StringArray{"Berlin", "Toronto"}}
+	assert.Nil(t, list.IsValidValue("Toronto"))
+	assert.NotNil(t, list.IsValidValue("toronto"))
+	assert.NotNil(t, list.IsValidValue("Paris"))
+
+	date := UserAttributeField{Type: USER_ATTRIBUTE_TYPE_DATE}
+	assert.Nil(t, date.IsValidValue("2018-02-28"))
+	assert.NotNil(t, date.IsValidValue("2018-02-30"))
+	assert.NotNil(t, date.IsValidValue("28/02/2018"))
+}
+
+func TestUserAttributeFieldPatch(t *testing.T) {
+	field := UserAttributeField{
+		Name:       "Office",
+		Type:       USER_ATTRIBUTE_TYPE_TEXT,
+		Visibility: USER_ATTRIBUTE_VISIBILITY_PUBLIC,
+	}
+
+	fieldType := USER_ATTRIBUTE_TYPE_SELECT
+	options := StringArray{"Berlin"}
+	readOnly := true
+	field.Patch(&UserAttributeFieldPatch{Type: &fieldType, Options: &options, ReadOnly: &readOnly})
+
+	assert.Equal(t, "Office", field.Name)
+	assert.Equal(t, USER_ATTRIBUTE_TYPE_SELECT, field.Type)
+	assert.Equal(t, StringArray{"Berlin"}, field.Options)
+	assert.Equal(t, USER_ATTRIBUTE_VISIBILITY_PUBLIC, field.Visibility)
+	assert.True(t, field.ReadOnly)
+}
+
+func TestUserAttributeFieldIsVisibleTo(t *testing.T) {
+	field := UserAttributeField{Visibility: USER_ATTRIBUTE_VISIBILITY_PUBLIC}
+	assert.True(t, field.IsVisibleTo(false))
+
+	field.Visibility = USER_ATTRIBUTE_VISIBILITY_PRIVATE
+	assert.False(t, field.IsVisibleTo(false))
+	assert.True(t, field.IsVisibleTo(true))
+}
+
+func TestUserAttributeIsValid(t *testing.T) {
+	attribute := UserAttribute{UserId: NewId(), FieldId: NewId(), Value: "Engineering"}
+	assert.Nil(t, attribute.IsValid())
+
+	attribute.Value = ""
+	assert.NotNil(t, attribute.IsValid())
+
+	attribute.Value = "Engineering"
+	attribute.FieldId = "junk"
+	assert.NotNil(t, attribute.IsValid())
+}
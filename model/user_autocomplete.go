@@ -18,8 +18,9 @@ type UserAutocompleteInTeam struct {
 }
 
 type UserAutocomplete struct {
-	Users        []*User `json:"users"`
-	OutOfChannel []*User `json:"out_of_channel,omitempty"`
+	Users        []*User        `json:"users"`
+	OutOfChannel []*User        `json:"out_of_channel,omitempty"`
+	Groups       []*CustomGroup `json:"groups,omitempty"`
 }
 
 func (o *UserAutocomplete) ToJson() string {
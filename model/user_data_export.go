@@ -0,0 +1,48 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+const (
+	USER_DATA_EXPORT_PROFILE_FILE     = "profile.json"
+	USER_DATA_EXPORT_PREFERENCES_FILE = "preferences.json"
+	USER_DATA_EXPORT_POSTS_FILE       = "posts.json"
+	USER_DATA_EXPORT_REACTIONS_FILE   = "reactions.json"
+	USER_DATA_EXPORT_FILES_FILE       = "files.json"
+	USER_DATA_EXPORT_SESSIONS_FILE    = "sessions.json"
+	USER_DATA_EXPORT_AUDITS_FILE      = "audits.json"
+	USER_DATA_EXPORT_FILES_DIRECTORY  = "files"
+
+	USER_ANONYMIZED_USERNAME_PREFIX = "anonymous-"
+	USER_ANONYMIZED_EMAIL_DOMAIN    = "anonymous.invalid"
+)
+
+// UserDataExportPost is a post written by the exported user along with the channel and team it was
+// posted in, so that the export can be understood on its own.
+type UserDataExportPost struct {
+	Post               *Post  `json:"post"`
+	ChannelName        string `json:"channel_name"`
+	ChannelDisplayName string `json:"channel_display_name"`
+	ChannelType        string `json:"channel_type"`
+	TeamName           string `json:"team_name,omitempty"`
+	TeamDisplayName    string `json:"team_display_name,omitempty"`
+}
+
+// UserDataExportSummary counts how much of each kind of data a user data export contains.
+type UserDataExportSummary struct {
+	Preferences int `json:"preferences"`
+	Posts       int `json:"posts"`
+	Reactions   int `json:"reactions"`
+	Files       int `json:"files"`
+	Sessions    int `json:"sessions"`
+	Audits      int `json:"audits"`
+}
+
+// UserAnonymizeResult describes the changes made, or that would be made in a dry run, when anonymizing a user.
+type UserAnonymizeResult struct {
+	DryRun         bool   `json:"dry_run"`
+	OldUsername    string `json:"old_username"`
+	Username       string `json:"username"`
+	Email          string `json:"email"`
+	PostsRewritten int    `json:"posts_rewritten"`
+}
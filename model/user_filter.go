@@ -0,0 +1,74 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// UserFilter limits a list of users to those matching all of its set fields. Times are in milliseconds, and a
+// zero value leaves that bound open.
+type UserFilter struct {
+	TeamId string `json:"team_id,omitempty"`
+
+	// Role matches users that have the given system role, such as system_admin.
+	Role string `json:"role,omitempty"`
+
+	// AuthService matches users that sign in with the given service. Users that sign in with their email address
+	// and password are matched by USER_AUTH_SERVICE_EMAIL.
+	AuthService string `json:"auth_service,omitempty"`
+
+	// Active matches users that are active, or deactivated if false. Both are matched if it's nil.
+	Active *bool `json:"active,omitempty"`
+
+	CreatedAfter  int64 `json:"created_after,omitempty"`
+	CreatedBefore int64 `json:"created_before,omitempty"`
+
+	// LastSeenBefore also matches users that have never been seen.
+	LastSeenAfter  int64 `json:"last_seen_after,omitempty"`
+	LastSeenBefore int64 `json:"last_seen_before,omitempty"`
+}
+
+// IsEmpty returns true if the filter doesn't limit users by anything other than their team.
+func (f *UserFilter) IsEmpty() bool {
+	return f.Role == "" && f.AuthService == "" && f.Active == nil &&
+		f.CreatedAfter == 0 && f.CreatedBefore == 0 && f.LastSeenAfter == 0 && f.LastSeenBefore == 0
+}
+
+func (f *UserFilter) IsValid() *AppError {
+	if f.TeamId != "" && !IsValidId(f.TeamId) {
+		return NewAppError("UserFilter.IsValid", "model.user_filter.is_valid.team_id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if f.Role != "" && !IsValidRoleName(f.Role) {
+		return NewAppError("UserFilter.IsValid", "model.user_filter.is_valid.role.app_error", nil, "role="+f.Role, http.StatusBadRequest)
+	}
+
+	if len(f.AuthService) > USER_AUTH_SERVICE_MAX_LENGTH {
+		return NewAppError("UserFilter.IsValid", "model.user_filter.is_valid.auth_service.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if f.CreatedAfter < 0 || f.CreatedBefore < 0 || (f.CreatedBefore != 0 && f.CreatedAfter >= f.CreatedBefore) {
+		return NewAppError("UserFilter.IsValid", "model.user_filter.is_valid.created.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if f.LastSeenAfter < 0 || f.LastSeenBefore < 0 || (f.LastSeenBefore != 0 && f.LastSeenAfter >= f.LastSeenBefore) {
+		return NewAppError("UserFilter.IsValid", "model.user_filter.is_valid.last_seen.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+func (f *UserFilter) ToJson() string {
+	b, _ := json.Marshal(f)
+	return string(b)
+}
+
+func UserFilterFromJson(data io.Reader) *UserFilter {
+	var f *UserFilter
+	json.NewDecoder(data).Decode(&f)
+	return f
+}
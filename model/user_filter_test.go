@@ -0,0 +1,51 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserFilterIsValid(t *testing.T) {
+	filter := UserFilter{}
+	assert.True(t, filter.IsEmpty())
+	assert.Nil(t, filter.IsValid())
+
+	filter.TeamId = NewId()
+	assert.True(t, filter.IsEmpty(), "a team alone doesn't filter users")
+
+	active := false
+	filter = UserFilter{
+		Role:           SYSTEM_ADMIN_ROLE_ID,
+		AuthService:    USER_AUTH_SERVICE_LDAP,
+		Active:         &active,
+		CreatedAfter:   1000,
+		CreatedBefore:  2000,
+		LastSeenBefore: 3000,
+	}
+	assert.False(t, filter.IsEmpty())
+	assert.Nil(t, filter.IsValid())
+
+	for name, invalid := range map[string]UserFilter{
+		"team":             {TeamId: "junk"},
+		"role":             {Role: "System Admin"},
+		"auth service":     {AuthService: strings.Repeat("a", USER_AUTH_SERVICE_MAX_LENGTH+1)},
+		"created range":    {CreatedAfter: 2000, CreatedBefore: 1000},
+		"negative created": {CreatedAfter: -1},
+		"last seen range":  {LastSeenAfter: 2000, LastSeenBefore: 2000},
+	} {
+		assert.NotNil(t, invalid.IsValid(), name)
+	}
+}
+
+func TestUserFilterJson(t *testing.T) {
+	active := true
+	filter := UserFilter{Role: SYSTEM_USER_ROLE_ID, Active: &active, LastSeenAfter: 1000}
+
+	decoded := UserFilterFromJson(strings.NewReader(filter.ToJson()))
+	assert.Equal(t, filter, *decoded)
+}
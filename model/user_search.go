@@ -16,6 +16,10 @@ type UserSearch struct {
 	NotInChannelId string `json:"not_in_channel_id"`
 	AllowInactive  bool   `json:"allow_inactive"`
 	WithoutTeam    bool   `json:"without_team"`
+
+	// Attributes limits the results to users whose custom profile attributes have the given values, keyed by
+	// field id.
+	Attributes StringMap `json:"attributes,omitempty"`
 }
 
 // ToJson convert a User to a json string
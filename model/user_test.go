@@ -64,6 +64,27 @@ func TestUserJson(t *testing.T) {
 	}
 }
 
+func TestUserListToSparseJson(t *testing.T) {
+	users := []*User{
+		{Id: NewId(), Username: "user1", Email: "user1@example.com", NotifyProps: StringMap{"email": "true"}},
+		{Id: NewId(), Username: "user2", IsBot: true},
+	}
+
+	assert.Equal(t, UserListToJson(users), UserListToSparseJson(users, nil))
+
+	json := UserListToSparseJson(users, []string{"id", "username", "is_bot", "notify_props"})
+	assert.NotContains(t, json, "email")
+	assert.NotContains(t, json, "notify_props")
+	assert.Equal(t, 1, strings.Count(json, "is_bot"), "empty fields should be left out like they are by ToJson")
+
+	rusers := UserListFromJson(strings.NewReader(json))
+	assert.Len(t, rusers, 2)
+	assert.Equal(t, users[0].Id, rusers[0].Id)
+	assert.Equal(t, users[1].Username, rusers[1].Username)
+	assert.True(t, rusers[1].IsBot)
+	assert.Empty(t, rusers[0].Email)
+}
+
 func TestUserPreSave(t *testing.T) {
 	user := User{Password: "test"}
 	user.PreSave()
@@ -274,6 +295,26 @@ var usernames = []struct {
 	{"all", false},
 }
 
+func TestUserGetInitials(t *testing.T) {
+	user := User{Username: "john.doe"}
+	assert.Equal(t, "JD", user.GetInitials(SHOW_FULLNAME), "the username should be used without a full name")
+	assert.Equal(t, "JD", user.GetInitials(SHOW_USERNAME))
+
+	user.Username = "jdoe"
+	assert.Equal(t, "J", user.GetInitials(SHOW_USERNAME))
+
+	user.FirstName = "mary-jane"
+	user.LastName = "Watson"
+	assert.Equal(t, "MW", user.GetInitials(SHOW_FULLNAME))
+	assert.Equal(t, "J", user.GetInitials(SHOW_USERNAME))
+
+	user.Nickname = "(émile)"
+	assert.Equal(t, "É", user.GetInitials(SHOW_NICKNAME_FULLNAME))
+
+	user = User{Username: "_"}
+	assert.Equal(t, "", user.GetInitials(SHOW_USERNAME))
+}
+
 func TestValidUsername(t *testing.T) {
 	for _, v := range usernames {
 		if IsValidUsername(v.value) != v.expected {
@@ -350,3 +391,34 @@ func TestRoles(t *testing.T) {
 		t.Fatal()
 	}
 }
+
+func TestUserIsGuest(t *testing.T) {
+	user := &User{Id: NewId(), Roles: SYSTEM_GUEST_ROLE_ID}
+	assert.True(t, user.IsGuest())
+	assert.Contains(t, user.ToJson(), `"is_guest":true`)
+
+	decoded := UserFromJson(strings.NewReader(user.ToJson()))
+	if assert.NotNil(t, decoded) {
+		assert.Equal(t, user.Id, decoded.Id)
+	}
+
+	user.Roles = SYSTEM_USER_ROLE_ID
+	assert.False(t, user.IsGuest())
+	assert.NotContains(t, user.ToJson(), "is_guest")
+
+	session := &Session{Roles: SYSTEM_GUEST_ROLE_ID}
+	assert.True(t, session.IsGuest())
+}
+
+func TestUserGetPreferredTimezone(t *testing.T) {
+	user := &User{Timezone: StringMap{"useAutomaticTimezone": "true", "automaticTimezone": "Asia/Tokyo", "manualTimezone": "America/Bogota"}}
+	assert.Equal(t, "Asia/Tokyo", user.GetPreferredTimezone())
+
+	user.Timezone["useAutomaticTimezone"] = "false"
+	assert.Equal(t, "America/Bogota", user.GetPreferredTimezone())
+
+	user.Timezone["manualTimezone"] = ""
+	assert.Equal(t, "", user.GetPreferredTimezone())
+
+	assert.Equal(t, "", (&User{}).GetPreferredTimezone())
+}
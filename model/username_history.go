@@ -0,0 +1,42 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"net/http"
+)
+
+// USERNAME_HISTORY_MAX_PER_USER is how many of a user's old usernames are kept. The oldest ones are forgotten once
+// there are more than this.
+const USERNAME_HISTORY_MAX_PER_USER = 10
+
+// UsernameHistory records that a user used to be called Username, so that direct channels can still be opened by it
+// after they've changed it. Each old username can belong to at most one user.
+type UsernameHistory struct {
+	Username string `json:"username"`
+	UserId   string `json:"user_id"`
+	CreateAt int64  `json:"create_at"`
+}
+
+func (o *UsernameHistory) PreSave() {
+	if o.CreateAt == 0 {
+		o.CreateAt = GetMillis()
+	}
+}
+
+func (o *UsernameHistory) IsValid() *AppError {
+	if !IsValidUsername(o.Username) {
+		return NewAppError("UsernameHistory.IsValid", "model.username_history.is_valid.username.app_error", nil, "user_id="+o.UserId, http.StatusBadRequest)
+	}
+
+	if len(o.UserId) != 26 {
+		return NewAppError("UsernameHistory.IsValid", "model.username_history.is_valid.user_id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if o.CreateAt == 0 {
+		return NewAppError("UsernameHistory.IsValid", "model.username_history.is_valid.create_at.app_error", nil, "user_id="+o.UserId, http.StatusBadRequest)
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsernameHistoryIsValid(t *testing.T) {
+	history := &UsernameHistory{
+		Username: "old.name",
+		UserId:   NewId(),
+	}
+	require.NotNil(t, history.IsValid())
+
+	history.PreSave()
+	require.Nil(t, history.IsValid())
+
+	history.Username = "Old Name"
+	require.NotNil(t, history.IsValid())
+	history.Username = "old.name"
+
+	history.UserId = "junk"
+	require.NotNil(t, history.IsValid())
+}
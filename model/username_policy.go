@@ -0,0 +1,35 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+)
+
+const (
+	USERNAME_POLICY_VIOLATION_PATTERN       = "pattern"
+	USERNAME_POLICY_VIOLATION_RESERVED      = "reserved"
+	USERNAME_POLICY_VIOLATION_IMPERSONATION = "impersonation"
+)
+
+// UsernamePolicyViolation describes how an existing user breaks the username policy, such as if their username
+// doesn't match the required pattern or their name matches the real name of other users.
+type UsernamePolicyViolation struct {
+	UserId              string   `json:"user_id"`
+	Username            string   `json:"username"`
+	Violation           string   `json:"violation"`
+	ImpersonatedUserIds []string `json:"impersonated_user_ids,omitempty"`
+}
+
+func UsernamePolicyViolationListToJson(violations []*UsernamePolicyViolation) string {
+	b, _ := json.Marshal(violations)
+	return string(b)
+}
+
+func UsernamePolicyViolationListFromJson(data io.Reader) []*UsernamePolicyViolation {
+	var violations []*UsernamePolicyViolation
+	json.NewDecoder(data).Decode(&violations)
+	return violations
+}
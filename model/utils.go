@@ -25,6 +25,8 @@ import (
 
 	goi18n "github.com/nicksnyder/go-i18n/i18n"
 	"github.com/pborman/uuid"
+
+	"github.com/mattermost/mattermost-server/utils/markdown"
 )
 
 const (
@@ -52,6 +54,7 @@ type AppError struct {
 	StatusCode    int    `json:"status_code,omitempty"` // The http status code
 	Where         string `json:"-"`                     // The function where it happened in the form of Struct.Func
 	IsOAuth       bool   `json:"is_oauth,omitempty"`    // Whether the error is OAuth specific
+	Code          string `json:"code,omitempty"`        // The machine code for the error from the AppError catalog
 	params        map[string]interface{}
 }
 
@@ -114,6 +117,7 @@ func NewAppError(where string, id string, params map[string]interface{}, details
 	ap.DetailedError = details
 	ap.StatusCode = status
 	ap.IsOAuth = false
+	ap.Code = GetAppErrorCode(id, status)
 	ap.Translate(translateFunc)
 	return ap
 }
@@ -278,6 +282,17 @@ func IsLower(s string) bool {
 	return strings.ToLower(s) == s
 }
 
+// HasFourByteCharacters returns true if some of the characters in s take four bytes in UTF-8, such as most emoji,
+// which databases using MySQL's utf8 charset can't store.
+func HasFourByteCharacters(s string) bool {
+	for _, r := range s {
+		if r > 0xFFFF {
+			return true
+		}
+	}
+	return false
+}
+
 func IsValidEmail(email string) bool {
 
 	if !IsLower(email) {
@@ -536,3 +551,58 @@ func checkNowhereNil(t *testing.T, name string, value interface{}) bool {
 		return true
 	}
 }
+
+var scriptLinkSchemes = []string{"javascript", "vbscript", "data"}
+
+// isScriptLink returns whether link runs a script when it's clicked instead of going somewhere. Browsers ignore
+// leading spaces and any tabs or newlines in the scheme, so they're ignored here too.
+func isScriptLink(link string) bool {
+	link = strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' {
+			return -1
+		}
+		return r
+	}, link)
+	link = strings.ToLower(strings.TrimLeftFunc(link, func(r rune) bool { return r <= ' ' }))
+
+	for _, scheme := range scriptLinkSchemes {
+		if strings.HasPrefix(link, scheme+":") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FindUnsafeMarkdownLink returns the destination of the first link or image in text that runs a script, or an empty
+// string if there aren't any.
+func FindUnsafeMarkdownLink(text string) string {
+	unsafe := ""
+
+	markdown.Inspect(text, func(blockOrInline interface{}) bool {
+		if unsafe != "" {
+			return false
+		}
+
+		var destination string
+		switch v := blockOrInline.(type) {
+		case *markdown.InlineLink:
+			destination = v.Destination()
+		case *markdown.InlineImage:
+			destination = v.Destination()
+		case *markdown.ReferenceLink:
+			destination = v.ReferenceDefinition.Destination()
+		case *markdown.ReferenceImage:
+			destination = v.ReferenceDefinition.Destination()
+		default:
+			return true
+		}
+
+		if isScriptLink(destination) {
+			unsafe = destination
+		}
+		return true
+	})
+
+	return unsafe
+}
@@ -97,6 +97,13 @@ func TestValidLower(t *testing.T) {
 	}
 }
 
+func TestHasFourByteCharacters(t *testing.T) {
+	assert.False(t, HasFourByteCharacters(""))
+	assert.False(t, HasFourByteCharacters("plain text, accents like é and symbols like ☺"))
+	assert.True(t, HasFourByteCharacters("a smiley 🙂"))
+	assert.True(t, HasFourByteCharacters("𝐛𝐨𝐥𝐝"))
+}
+
 func TestEtag(t *testing.T) {
 	etag := Etag("hello", 24)
 	if len(etag) <= 0 {
@@ -562,3 +569,38 @@ func TestNowhereNil(t *testing.T) {
 		})
 	}
 }
+
+func TestIsScriptLink(t *testing.T) {
+	for link, expected := range map[string]bool{
+		"https://www.example.com":      false,
+		"mailto:someone@example.com":   false,
+		"/relative/path":               false,
+		"#anchor":                      false,
+		"https://example.com/?q=data:": false,
+		"javascript:alert(1)":          true,
+		"JavaScript:alert(1)":          true,
+		"  javascript:alert(1)":        true,
+		"java\tscript:alert(1)":        true,
+		"jav\nascript:alert(1)":        true,
+		"vbscript:msgbox(1)":           true,
+		"data:text/html,<script>":      true,
+	} {
+		assert.Equal(t, expected, isScriptLink(link), link)
+	}
+}
+
+func TestFindUnsafeMarkdownLink(t *testing.T) {
+	for text, expected := range map[string]string{
+		"no links": "",
+		"[runbook](https://wiki.example.com/runbook)":   "",
+		"[click](javascript:alert(1))":                  "javascript:alert(1)",
+		"![image](javascript:alert(1))":                 "javascript:alert(1)",
+		"[ok](https://example.com) [bad](vbscript:x)":   "vbscript:x",
+		"[ref][1]\n\n[1]: javascript:alert(1)":          "javascript:alert(1)",
+		"> * [nested](javascript&#58;alert(1))":         "javascript:alert(1)",
+		"`[code](javascript:alert(1))`":                 "",
+		"javascript:alert(1) isn't a link without text": "",
+	} {
+		assert.Equal(t, expected, FindUnsafeMarkdownLink(text), text)
+	}
+}
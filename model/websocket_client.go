@@ -149,7 +149,8 @@ func (wsc *WebSocketClient) SendMessage(action string, data map[string]interface
 }
 
 // UserTyping will push a user_typing event out to all connected users
-// who are in the specified channel
+// who are in the specified channel. If parentId is set, the event is only
+// sent to the users following or viewing the thread that it belongs to
 func (wsc *WebSocketClient) UserTyping(channelId, parentId string) {
 	data := map[string]interface{}{
 		"channel_id": channelId,
@@ -159,6 +160,17 @@ func (wsc *WebSocketClient) UserTyping(channelId, parentId string) {
 	wsc.SendMessage("user_typing", data)
 }
 
+// ViewThread tells the server which thread is open on this connection, so that
+// typing events for it are sent here even if the user isn't following it. An
+// empty threadId means that no thread is open
+func (wsc *WebSocketClient) ViewThread(threadId string) {
+	data := map[string]interface{}{
+		"thread_id": threadId,
+	}
+
+	wsc.SendMessage("view_thread", data)
+}
+
 // GetStatuses will return a map of string statuses using user id as the key
 func (wsc *WebSocketClient) GetStatuses() {
 	wsc.SendMessage("get_statuses", nil)
@@ -173,6 +185,24 @@ func (wsc *WebSocketClient) GetStatusesByIds(userIds []string) {
 	wsc.SendMessage("get_statuses_by_ids", data)
 }
 
+// SubscribeToStatuses asks for status change events to be sent for the given users and returns their current statuses
+// as a map of string statuses using user id as the key. The server must have presence subscriptions enabled.
+func (wsc *WebSocketClient) SubscribeToStatuses(userIds []string) {
+	data := map[string]interface{}{
+		"user_ids": userIds,
+	}
+	wsc.SendMessage("subscribe_statuses", data)
+}
+
+// UnsubscribeFromStatuses stops status change events from being sent for the given users, or for every user that's
+// been subscribed to if no ids are given.
+func (wsc *WebSocketClient) UnsubscribeFromStatuses(userIds []string) {
+	data := map[string]interface{}{
+		"user_ids": userIds,
+	}
+	wsc.SendMessage("unsubscribe_statuses", data)
+}
+
 func (wsc *WebSocketClient) configurePingHandling() {
 	wsc.Conn.SetPingHandler(wsc.pingHandler)
 	wsc.pingTimeoutTimer = time.NewTimer(time.Second * (60 + PING_TIMEOUT_BUFFER_SECONDS))
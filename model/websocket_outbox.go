@@ -0,0 +1,87 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	WEBSOCKET_OUTBOX_EVENT_MAX_LENGTH      = 64
+	WEBSOCKET_OUTBOX_EVENT_DATA_MAX_LENGTH = 65535
+)
+
+// WebSocketOutboxEvent is a websocket event that's written in the same transaction as the change that it's about, so
+// that it can be sent again if the server stops after making the change but before sending the event. The posted
+// event is made from the post when it's sent, since most of it is only known after the post is saved, so it only
+// records the post's id. Every other event records the whole websocket event in Data.
+type WebSocketOutboxEvent struct {
+	Id       string `json:"id"`
+	Event    string `json:"event"`
+	PostId   string `json:"post_id"`
+	Data     string `json:"data"`
+	CreateAt int64  `json:"create_at"`
+	SentAt   int64  `json:"sent_at"`
+}
+
+func NewWebSocketOutboxEvent(message *WebSocketEvent) *WebSocketOutboxEvent {
+	return &WebSocketOutboxEvent{
+		Event: message.Event,
+		Data:  message.ToJson(),
+	}
+}
+
+// NewPostedWebSocketOutboxEvent returns the outbox event for a new post. It has the same id as the post so that it
+// can be marked as sent by the code that sends the posted event.
+func NewPostedWebSocketOutboxEvent(post *Post) *WebSocketOutboxEvent {
+	return &WebSocketOutboxEvent{
+		Id:     post.Id,
+		Event:  WEBSOCKET_EVENT_POSTED,
+		PostId: post.Id,
+	}
+}
+
+func (o *WebSocketOutboxEvent) PreSave() {
+	if o.Id == "" {
+		o.Id = NewId()
+	}
+
+	if o.CreateAt == 0 {
+		o.CreateAt = GetMillis()
+	}
+}
+
+func (o *WebSocketOutboxEvent) IsValid() *AppError {
+	if !IsValidId(o.Id) {
+		return NewAppError("WebSocketOutboxEvent.IsValid", "model.websocket_outbox_event.is_valid.id.app_error", nil, "", http.StatusBadRequest)
+	}
+
+	if o.Event == "" || len(o.Event) > WEBSOCKET_OUTBOX_EVENT_MAX_LENGTH {
+		return NewAppError("WebSocketOutboxEvent.IsValid", "model.websocket_outbox_event.is_valid.event.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	if o.PostId != "" && !IsValidId(o.PostId) {
+		return NewAppError("WebSocketOutboxEvent.IsValid", "model.websocket_outbox_event.is_valid.post_id.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	if (o.PostId == "") == (o.Data == "") || len(o.Data) > WEBSOCKET_OUTBOX_EVENT_DATA_MAX_LENGTH {
+		return NewAppError("WebSocketOutboxEvent.IsValid", "model.websocket_outbox_event.is_valid.data.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	if o.CreateAt == 0 {
+		return NewAppError("WebSocketOutboxEvent.IsValid", "model.websocket_outbox_event.is_valid.create_at.app_error", nil, "id="+o.Id, http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+// WebSocketEvent returns the recorded websocket event, or nil if the event only records a post.
+func (o *WebSocketOutboxEvent) WebSocketEvent() *WebSocketEvent {
+	if o.Data == "" {
+		return nil
+	}
+
+	return WebSocketEventFromJson(strings.NewReader(o.Data))
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebSocketOutboxEvent(t *testing.T) {
+	message := NewWebSocketEvent(WEBSOCKET_EVENT_REACTION_ADDED, "", NewId(), "", nil)
+	message.Add("reaction", "{}")
+
+	event := NewWebSocketOutboxEvent(message)
+	event.PreSave()
+	require.Nil(t, event.IsValid())
+	assert.Equal(t, WEBSOCKET_EVENT_REACTION_ADDED, event.Event)
+
+	decoded := event.WebSocketEvent()
+	require.NotNil(t, decoded)
+	assert.Equal(t, message.Event, decoded.Event)
+	assert.Equal(t, message.Broadcast.ChannelId, decoded.Broadcast.ChannelId)
+	assert.Equal(t, "{}", decoded.Data["reaction"])
+
+	post := &Post{Id: NewId()}
+	event = NewPostedWebSocketOutboxEvent(post)
+	event.PreSave()
+	require.Nil(t, event.IsValid())
+	assert.Equal(t, post.Id, event.Id)
+	assert.Nil(t, event.WebSocketEvent())
+
+	event.Data = message.ToJson()
+	assert.NotNil(t, event.IsValid(), "an event can't record both a post and a websocket event")
+
+	event = &WebSocketOutboxEvent{Event: WEBSOCKET_EVENT_POSTED}
+	event.PreSave()
+	assert.NotNil(t, event.IsValid(), "an event has to record a post or a websocket event")
+}
@@ -0,0 +1,31 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"bytes"
+	"text/template"
+)
+
+// WelcomeMessageValues are the placeholders that can be used in a welcome message.
+type WelcomeMessageValues struct {
+	UserFirstName string
+	TeamName      string
+}
+
+// RenderWelcomeMessage fills in the placeholders of a welcome message, which is written with the text/template
+// syntax, and returns an error if the message uses a placeholder that doesn't exist.
+func RenderWelcomeMessage(message string, values WelcomeMessageValues) (string, error) {
+	t, err := template.New("welcome_message").Parse(message)
+	if err != nil {
+		return "", err
+	}
+
+	var text bytes.Buffer
+	if err := t.Execute(&text, values); err != nil {
+		return "", err
+	}
+
+	return text.String(), nil
+}
@@ -0,0 +1,30 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderWelcomeMessage(t *testing.T) {
+	text, err := RenderWelcomeMessage("Welcome to {{.TeamName}}, {{.UserFirstName}}!", WelcomeMessageValues{UserFirstName: "Jo", TeamName: "Engineering"})
+	require.Nil(t, err)
+	assert.Equal(t, "Welcome to Engineering, Jo!", text)
+
+	text, err = RenderWelcomeMessage("", WelcomeMessageValues{})
+	require.Nil(t, err)
+	assert.Equal(t, "", text)
+
+	_, err = RenderWelcomeMessage("Welcome {{.Username}}", WelcomeMessageValues{})
+	assert.NotNil(t, err)
+
+	_, err = RenderWelcomeMessage("Welcome {{.UserFirstName", WelcomeMessageValues{})
+	assert.NotNil(t, err)
+
+	_, err = RenderWelcomeMessage(ANNOUNCEMENT_SETTINGS_DEFAULT_WELCOME_MESSAGE, WelcomeMessageValues{})
+	assert.Nil(t, err)
+}
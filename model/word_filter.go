@@ -0,0 +1,127 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	FILTERED_WORD_MAX_RUNES   = 64
+	FILTERED_WORDS_MAX_COUNT  = 1000
+	FILTERED_WORDS_CACHE_SIZE = 5000
+)
+
+// WordFilter finds whole words from a list in text, ignoring case. Words are runs of letters, numbers and combining
+// marks in any script, so a filtered word is never matched in the middle of a longer one. Scripts that aren't
+// written with spaces between words only match where the word is set apart by punctuation or spaces.
+type WordFilter struct {
+	words map[string]bool
+}
+
+// NewWordFilter creates a filter that matches every word in the given lists.
+func NewWordFilter(lists ...[]string) *WordFilter {
+	filter := &WordFilter{
+		words: make(map[string]bool),
+	}
+
+	for _, list := range lists {
+		for _, word := range list {
+			if word = NormalizeFilteredWord(word); word != "" {
+				filter.words[word] = true
+			}
+		}
+	}
+
+	return filter
+}
+
+// IsEmpty returns true if the filter has no words to match.
+func (f *WordFilter) IsEmpty() bool {
+	return len(f.words) == 0
+}
+
+// Filter returns text with every filtered word in it replaced by as many asterisks as it has characters, along with
+// the filtered words that were found in the order that they first appear.
+func (f *WordFilter) Filter(text string) (string, []string) {
+	if f.IsEmpty() {
+		return text, []string{}
+	}
+
+	var masked strings.Builder
+	found := []string{}
+	seen := make(map[string]bool)
+
+	last := 0
+	forEachWord(text, func(start, end int) {
+		word := strings.ToLower(text[start:end])
+		if !f.words[word] {
+			return
+		}
+
+		if !seen[word] {
+			seen[word] = true
+			found = append(found, word)
+		}
+
+		masked.WriteString(text[last:start])
+		masked.WriteString(strings.Repeat("*", utf8.RuneCountInString(text[start:end])))
+		last = end
+	})
+
+	if len(found) == 0 {
+		return text, found
+	}
+
+	masked.WriteString(text[last:])
+
+	return masked.String(), found
+}
+
+// forEachWord calls f with the byte offsets of the start and end of each word in text.
+func forEachWord(text string, f func(start, end int)) {
+	start := -1
+
+	for i, r := range text {
+		if isWordRune(r) {
+			if start == -1 {
+				start = i
+			}
+		} else if start != -1 {
+			f(start, i)
+			start = -1
+		}
+	}
+
+	if start != -1 {
+		f(start, len(text))
+	}
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r)
+}
+
+// NormalizeFilteredWord returns the lowercase form of a word that's going to be filtered, without any surrounding
+// whitespace.
+func NormalizeFilteredWord(word string) string {
+	return strings.ToLower(strings.TrimSpace(word))
+}
+
+// IsValidFilteredWord returns true if a word can be filtered. It has to be a single word as defined by WordFilter.
+func IsValidFilteredWord(word string) bool {
+	if word == "" || utf8.RuneCountInString(word) > FILTERED_WORD_MAX_RUNES {
+		return false
+	}
+
+	for _, r := range word {
+		if !isWordRune(r) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWordFilter(t *testing.T) {
+	filter := NewWordFilter([]string{"Darn", " über "}, []string{"привет", "café", "東京"})
+
+	for name, tc := range map[string]struct {
+		Text     string
+		Expected string
+		Found    []string
+	}{
+		"no words": {
+			Text:     "nothing to see here",
+			Expected: "nothing to see here",
+			Found:    []string{},
+		},
+		"ignores case": {
+			Text:     "DARN it, darn it all",
+			Expected: "**** it, **** it all",
+			Found:    []string{"darn"},
+		},
+		"whole words only": {
+			Text:     "darned darnit undarn darn2",
+			Expected: "darned darnit undarn darn2",
+			Found:    []string{},
+		},
+		"punctuation separates words": {
+			Text:     "(darn)-darn_darn.",
+			Expected: "(****)-****_****.",
+			Found:    []string{"darn"},
+		},
+		"accented letters are part of words": {
+			Text:     "Über alles, but not überall or darnä",
+			Expected: "**** alles, but not überall or darnä",
+			Found:    []string{"über"},
+		},
+		"cyrillic": {
+			Text:     "Привет, мир! приветствую",
+			Expected: "******, мир! приветствую",
+			Found:    []string{"привет"},
+		},
+		// A combining accent doesn't end a word, so it only matches the precomposed spelling of the word in the list
+		"combining marks are part of words": {
+			Text:     "cafe\u0301 and caf\u00e9",
+			Expected: "cafe\u0301 and ****",
+			Found:    []string{"café"},
+		},
+		"scripts without spaces": {
+			Text:     "東京 and 東京タワー",
+			Expected: "** and 東京タワー",
+			Found:    []string{"東京"},
+		},
+		"several words": {
+			Text:     "über darn",
+			Expected: "**** ****",
+			Found:    []string{"über", "darn"},
+		},
+		"emoji separate words": {
+			Text:     "darn🙂darn",
+			Expected: "****🙂****",
+			Found:    []string{"darn"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			masked, found := filter.Filter(tc.Text)
+			assert.Equal(t, tc.Expected, masked)
+			assert.Equal(t, tc.Found, found)
+		})
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		empty := NewWordFilter(nil, []string{" "})
+		assert.True(t, empty.IsEmpty())
+
+		masked, found := empty.Filter("darn")
+		assert.Equal(t, "darn", masked)
+		assert.Empty(t, found)
+	})
+}
+
+func TestIsValidFilteredWord(t *testing.T) {
+	assert.True(t, IsValidFilteredWord("darn"))
+	assert.True(t, IsValidFilteredWord("über"))
+	assert.True(t, IsValidFilteredWord("東京"))
+	assert.True(t, IsValidFilteredWord("l33t"))
+
+	assert.False(t, IsValidFilteredWord(""))
+	assert.False(t, IsValidFilteredWord("two words"))
+	assert.False(t, IsValidFilteredWord("d*rn"))
+	assert.False(t, IsValidFilteredWord("snake_case"))
+	assert.False(t, IsValidFilteredWord(NewRandomString(FILTERED_WORD_MAX_RUNES+1)))
+}
@@ -41,6 +41,10 @@ type API interface {
 	// UpdateUser updates a user.
 	UpdateUser(user *model.User) (*model.User, *model.AppError)
 
+	// EnsureBotUser gets the system bot, creating it if it doesn't exist yet. Plugins can use it to send
+	// messages without creating a user of their own.
+	EnsureBotUser() (*model.User, *model.AppError)
+
 	// CreateTeam creates a team.
 	CreateTeam(team *model.Team) (*model.Team, *model.AppError)
 
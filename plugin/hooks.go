@@ -69,4 +69,11 @@ type Hooks interface {
 	// Note that this method will be called for posts created by plugins, including the plugin that
 	// created the post.
 	MessageHasBeenUpdated(newPost, oldPost *model.Post)
+
+	// VerifyCaptcha is invoked when a user logging in has solved a CAPTCHA and the server is
+	// configured to have this plugin check it, allowing CAPTCHA services other than reCAPTCHA to be
+	// used. It returns true if the token that the client got for solving the CAPTCHA is valid.
+	//
+	// The remote address is the address of the client as seen by the server.
+	VerifyCaptcha(token, remoteAddress string) (bool, *model.AppError)
 }
@@ -15,6 +15,7 @@ import (
 
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/plugin"
+	"github.com/mattermost/mattermost-server/utils/tracing"
 )
 
 type APIProviderFunc func(*model.Manifest) (plugin.API, error)
@@ -288,6 +289,8 @@ func (h *MultiPluginHooks) invoke(f func(plugin.Hooks) error) (errs []error) {
 // OnConfigurationChange invokes the OnConfigurationChange hook for all plugins. Any errors
 // encountered will be returned.
 func (h *MultiPluginHooks) OnConfigurationChange() []error {
+	defer tracing.StartSpan(model.REQUEST_TRACE_SPAN_PLUGIN_HOOK, "OnConfigurationChange")()
+
 	return h.invoke(func(hooks plugin.Hooks) error {
 		if err := hooks.OnConfigurationChange(); err != nil {
 			return errors.Wrapf(err, "error calling OnConfigurationChange hook")
@@ -301,6 +304,8 @@ func (h *MultiPluginHooks) OnConfigurationChange() []error {
 //
 // It expects the request's context to have a plugin_id set.
 func (h *MultiPluginHooks) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer tracing.StartSpan(model.REQUEST_TRACE_SPAN_PLUGIN_HOOK, "ServeHTTP")()
+
 	if id := r.Context().Value("plugin_id"); id != nil {
 		if idstr, ok := id.(string); ok {
 			h.env.mutex.RLock()
@@ -320,6 +325,8 @@ func (h *MultiPluginHooks) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 // Returns the final result post, or nil if the post was rejected and a string with a reason
 // for the user the message was rejected.
 func (h *MultiPluginHooks) MessageWillBePosted(post *model.Post) (*model.Post, string) {
+	defer tracing.StartSpan(model.REQUEST_TRACE_SPAN_PLUGIN_HOOK, "MessageWillBePosted")()
+
 	h.env.mutex.RLock()
 	defer h.env.mutex.RUnlock()
 
@@ -342,6 +349,8 @@ func (h *MultiPluginHooks) MessageWillBePosted(post *model.Post) (*model.Post, s
 // Returns the final result post, or nil if the post was rejected and a string with a reason
 // for the user the message was rejected.
 func (h *MultiPluginHooks) MessageWillBeUpdated(newPost, oldPost *model.Post) (*model.Post, string) {
+	defer tracing.StartSpan(model.REQUEST_TRACE_SPAN_PLUGIN_HOOK, "MessageWillBeUpdated")()
+
 	h.env.mutex.RLock()
 	defer h.env.mutex.RUnlock()
 
@@ -360,6 +369,8 @@ func (h *MultiPluginHooks) MessageWillBeUpdated(newPost, oldPost *model.Post) (*
 }
 
 func (h *MultiPluginHooks) MessageHasBeenPosted(post *model.Post) {
+	defer tracing.StartSpan(model.REQUEST_TRACE_SPAN_PLUGIN_HOOK, "MessageHasBeenPosted")()
+
 	h.invoke(func(hooks plugin.Hooks) error {
 		hooks.MessageHasBeenPosted(post)
 		return nil
@@ -367,6 +378,8 @@ func (h *MultiPluginHooks) MessageHasBeenPosted(post *model.Post) {
 }
 
 func (h *MultiPluginHooks) MessageHasBeenUpdated(newPost, oldPost *model.Post) {
+	defer tracing.StartSpan(model.REQUEST_TRACE_SPAN_PLUGIN_HOOK, "MessageHasBeenUpdated")()
+
 	h.invoke(func(hooks plugin.Hooks) error {
 		hooks.MessageHasBeenUpdated(newPost, oldPost)
 		return nil
@@ -394,3 +407,12 @@ func (h *SinglePluginHooks) ExecuteCommand(args *model.CommandArgs) (resp *model
 	})
 	return
 }
+
+// VerifyCaptcha invokes the VerifyCaptcha hook for the plugin.
+func (h *SinglePluginHooks) VerifyCaptcha(token, remoteAddress string) (verified bool, appErr *model.AppError, err error) {
+	err = h.invoke(func(hooks plugin.Hooks) error {
+		verified, appErr = hooks.VerifyCaptcha(token, remoteAddress)
+		return nil
+	})
+	return
+}
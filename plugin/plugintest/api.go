@@ -218,6 +218,31 @@ func (_m *APIMOCKINTERNAL) DeleteUser(userId string) *model.AppError {
 	return r0
 }
 
+// EnsureBotUser provides a mock function with given fields:
+func (_m *APIMOCKINTERNAL) EnsureBotUser() (*model.User, *model.AppError) {
+	ret := _m.Called()
+
+	var r0 *model.User
+	if rf, ok := ret.Get(0).(func() *model.User); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.User)
+		}
+	}
+
+	var r1 *model.AppError
+	if rf, ok := ret.Get(1).(func() *model.AppError); ok {
+		r1 = rf()
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*model.AppError)
+		}
+	}
+
+	return r0, r1
+}
+
 // GetChannel provides a mock function with given fields: channelId
 func (_m *APIMOCKINTERNAL) GetChannel(channelId string) (*model.Channel, *model.AppError) {
 	ret := _m.Called(channelId)
@@ -141,3 +141,26 @@ func (_m *Hooks) OnDeactivate() error {
 func (_m *Hooks) ServeHTTP(_a0 http.ResponseWriter, _a1 *http.Request) {
 	_m.Called(_a0, _a1)
 }
+
+// VerifyCaptcha provides a mock function with given fields: token, remoteAddress
+func (_m *Hooks) VerifyCaptcha(token string, remoteAddress string) (bool, *model.AppError) {
+	ret := _m.Called(token, remoteAddress)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string, string) bool); ok {
+		r0 = rf(token, remoteAddress)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 *model.AppError
+	if rf, ok := ret.Get(1).(func(string, string) *model.AppError); ok {
+		r1 = rf(token, remoteAddress)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*model.AppError)
+		}
+	}
+
+	return r0, r1
+}
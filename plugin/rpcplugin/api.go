@@ -149,6 +149,15 @@ func (api *LocalAPI) UpdateUser(args *model.User, reply *APIUserReply) error {
 	return nil
 }
 
+func (api *LocalAPI) EnsureBotUser(args struct{}, reply *APIUserReply) error {
+	user, err := api.api.EnsureBotUser()
+	*reply = APIUserReply{
+		User:  user,
+		Error: err,
+	}
+	return nil
+}
+
 type APIGetChannelByNameArgs struct {
 	Name   string
 	TeamId string
@@ -473,6 +482,14 @@ func (api *RemoteAPI) UpdateUser(user *model.User) (*model.User, *model.AppError
 	return reply.User, reply.Error
 }
 
+func (api *RemoteAPI) EnsureBotUser() (*model.User, *model.AppError) {
+	var reply APIUserReply
+	if err := api.client.Call("LocalAPI.EnsureBotUser", struct{}{}, &reply); err != nil {
+		return nil, model.NewAppError("RemoteAPI.EnsureBotUser", "plugin.rpcplugin.invocation.error", nil, "err="+err.Error(), http.StatusInternalServerError)
+	}
+	return reply.User, reply.Error
+}
+
 func (api *RemoteAPI) CreateTeam(team *model.Team) (*model.Team, *model.AppError) {
 	var reply APITeamReply
 	if err := api.client.Call("LocalAPI.CreateTeam", team, &reply); err != nil {
@@ -187,6 +187,11 @@ func TestAPI(t *testing.T) {
 		assert.Equal(t, testUser, user)
 		assert.Nil(t, err)
 
+		api.On("EnsureBotUser").Return(testUser, nil).Once()
+		user, err = remote.EnsureBotUser()
+		assert.Equal(t, testUser, user)
+		assert.Nil(t, err)
+
 		api.On("CreateTeam", mock.AnythingOfType("*model.Team")).Return(func(t *model.Team) *model.Team {
 			t.Id = "theteamid"
 			return t
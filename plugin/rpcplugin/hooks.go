@@ -187,6 +187,25 @@ func (h *LocalHooks) MessageHasBeenUpdated(args *MessageUpdatedArgs, reply *stru
 	return nil
 }
 
+type VerifyCaptchaArgs struct {
+	Token         string
+	RemoteAddress string
+}
+
+type VerifyCaptchaReply struct {
+	Verified bool
+	Error    *model.AppError
+}
+
+func (h *LocalHooks) VerifyCaptcha(args *VerifyCaptchaArgs, reply *VerifyCaptchaReply) error {
+	if hook, ok := h.hooks.(interface {
+		VerifyCaptcha(string, string) (bool, *model.AppError)
+	}); ok {
+		reply.Verified, reply.Error = hook.VerifyCaptcha(args.Token, args.RemoteAddress)
+	}
+	return nil
+}
+
 func ServeHooks(hooks interface{}, conn io.ReadWriteCloser, muxer *Muxer) {
 	server := rpc.NewServer()
 	server.Register(&LocalHooks{
@@ -208,6 +227,7 @@ const (
 	remoteMessageWillBeUpdated  = 6
 	remoteMessageHasBeenPosted  = 7
 	remoteMessageHasBeenUpdated = 8
+	remoteVerifyCaptcha         = 9
 	maxRemoteHookCount          = iota
 )
 
@@ -353,6 +373,21 @@ func (h *RemoteHooks) MessageHasBeenUpdated(newPost, oldPost *model.Post) {
 	}
 }
 
+func (h *RemoteHooks) VerifyCaptcha(token, remoteAddress string) (bool, *model.AppError) {
+	if !h.implemented[remoteVerifyCaptcha] {
+		return false, model.NewAppError("RemoteHooks.VerifyCaptcha", "plugin.rpcplugin.invocation.error", nil, "err=VerifyCaptcha hook not implemented", http.StatusInternalServerError)
+	}
+	var reply VerifyCaptchaReply
+	args := &VerifyCaptchaArgs{
+		Token:         token,
+		RemoteAddress: remoteAddress,
+	}
+	if err := h.client.Call("LocalHooks.VerifyCaptcha", args, &reply); err != nil {
+		return false, model.NewAppError("RemoteHooks.VerifyCaptcha", "plugin.rpcplugin.invocation.error", nil, "err="+err.Error(), http.StatusInternalServerError)
+	}
+	return reply.Verified, reply.Error
+}
+
 func (h *RemoteHooks) Close() error {
 	if h.apiCloser != nil {
 		h.apiCloser.Close()
@@ -392,6 +427,8 @@ func ConnectHooks(conn io.ReadWriteCloser, muxer *Muxer, pluginId string) (*Remo
 			remote.implemented[remoteMessageHasBeenPosted] = true
 		case "MessageHasBeenUpdated":
 			remote.implemented[remoteMessageHasBeenUpdated] = true
+		case "VerifyCaptcha":
+			remote.implemented[remoteVerifyCaptcha] = true
 		}
 	}
 	return remote, nil
@@ -115,6 +115,11 @@ func TestHooks(t *testing.T) {
 
 		hooks.On("MessageHasBeenUpdated", mock.AnythingOfType("*model.Post"), mock.AnythingOfType("*model.Post")).Return(nil)
 		remote.MessageHasBeenUpdated(&model.Post{}, &model.Post{})
+
+		hooks.On("VerifyCaptcha", "token", "127.0.0.1").Return(true, nil)
+		verified, appErr := remote.VerifyCaptcha("token", "127.0.0.1")
+		assert.True(t, verified)
+		assert.Nil(t, appErr)
 	}))
 }
 
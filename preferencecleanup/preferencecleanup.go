@@ -0,0 +1,19 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package preferencecleanup
+
+import (
+	"github.com/mattermost/mattermost-server/app"
+	tjobs "github.com/mattermost/mattermost-server/einterfaces/jobs"
+)
+
+type PreferenceCleanupJobInterfaceImpl struct {
+	App *app.App
+}
+
+func init() {
+	app.RegisterJobsPreferenceCleanupJobInterface(func(a *app.App) tjobs.PreferenceCleanupJobInterface {
+		return &PreferenceCleanupJobInterfaceImpl{a}
+	})
+}
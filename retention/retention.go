@@ -0,0 +1,19 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package retention
+
+import (
+	"github.com/mattermost/mattermost-server/app"
+	tjobs "github.com/mattermost/mattermost-server/einterfaces/jobs"
+)
+
+type BasicRetentionJobInterfaceImpl struct {
+	App *app.App
+}
+
+func init() {
+	app.RegisterJobsBasicRetentionJobInterface(func(a *app.App) tjobs.BasicRetentionJobInterface {
+		return &BasicRetentionJobInterfaceImpl{a}
+	})
+}
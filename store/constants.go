@@ -11,10 +11,13 @@ const (
 	MISSING_ACCOUNT_ERROR      = "store.sql_user.missing_account.const"
 	MISSING_AUTH_ACCOUNT_ERROR = "store.sql_user.get_by_auth.missing_account.app_error"
 
+	READ_ONLY_ERROR = "store.read_only.app_error"
+
 	USER_SEARCH_OPTION_NAMES_ONLY              = "names_only"
 	USER_SEARCH_OPTION_NAMES_ONLY_NO_FULL_NAME = "names_only_no_full_name"
 	USER_SEARCH_OPTION_ALL_NO_FULL_NAME        = "all_no_full_name"
 	USER_SEARCH_OPTION_ALLOW_INACTIVE          = "allow_inactive"
+	USER_SEARCH_OPTION_EXCLUDE_BOTS            = "exclude_bots"
 
 	FEATURE_TOGGLE_PREFIX = "feature_enabled_"
 )
@@ -58,9 +58,12 @@ type QueryFunction func(LayeredStoreSupplier) *LayeredStoreSupplierResult
 
 func (s *LayeredStore) RunQuery(queryFunction QueryFunction) StoreChannel {
 	storeChannel := make(StoreChannel)
+	endSpan := startSpan()
 
 	go func() {
 		result := queryFunction(s.LayerChainHead)
+		replaceReadOnlyError(&result.StoreResult)
+		endSpan()
 		storeChannel <- result.StoreResult
 	}()
 
@@ -159,6 +162,74 @@ func (s *LayeredStore) ChannelMemberHistory() ChannelMemberHistoryStore {
 	return s.DatabaseLayer.ChannelMemberHistory()
 }
 
+func (s *LayeredStore) Thread() ThreadStore {
+	return s.DatabaseLayer.Thread()
+}
+
+func (s *LayeredStore) CustomGroup() CustomGroupStore {
+	return s.DatabaseLayer.CustomGroup()
+}
+
+func (s *LayeredStore) UserAttribute() UserAttributeStore {
+	return s.DatabaseLayer.UserAttribute()
+}
+
+func (s *LayeredStore) RetentionPolicy() RetentionPolicyStore {
+	return s.DatabaseLayer.RetentionPolicy()
+}
+
+func (s *LayeredStore) TermsOfService() TermsOfServiceStore {
+	return s.DatabaseLayer.TermsOfService()
+}
+
+func (s *LayeredStore) AnalyticsDaily() AnalyticsDailyStore {
+	return s.DatabaseLayer.AnalyticsDaily()
+}
+
+func (s *LayeredStore) ProvisioningToken() ProvisioningTokenStore {
+	return s.DatabaseLayer.ProvisioningToken()
+}
+
+func (s *LayeredStore) TeamTemplate() TeamTemplateStore {
+	return s.DatabaseLayer.TeamTemplate()
+}
+
+func (s *LayeredStore) InviteLink() InviteLinkStore {
+	return s.DatabaseLayer.InviteLink()
+}
+
+func (s *LayeredStore) TeamInvitation() TeamInvitationStore {
+	return s.DatabaseLayer.TeamInvitation()
+}
+
+func (s *LayeredStore) ShortPermalink() ShortPermalinkStore {
+	return s.DatabaseLayer.ShortPermalink()
+}
+
+func (s *LayeredStore) ConfigurationHistory() ConfigurationHistoryStore {
+	return s.DatabaseLayer.ConfigurationHistory()
+}
+
+func (s *LayeredStore) FilePublicLink() FilePublicLinkStore {
+	return s.DatabaseLayer.FilePublicLink()
+}
+
+func (s *LayeredStore) FileStorageUsage() FileStorageUsageStore {
+	return s.DatabaseLayer.FileStorageUsage()
+}
+
+func (s *LayeredStore) BlockedDomain() BlockedDomainStore {
+	return s.DatabaseLayer.BlockedDomain()
+}
+
+func (s *LayeredStore) WebSocketOutbox() WebSocketOutboxStore {
+	return s.DatabaseLayer.WebSocketOutbox()
+}
+
+func (s *LayeredStore) PostIdempotencyKey() PostIdempotencyKeyStore {
+	return s.DatabaseLayer.PostIdempotencyKey()
+}
+
 func (s *LayeredStore) Plugin() PluginStore {
 	return s.DatabaseLayer.Plugin()
 }
@@ -191,6 +262,37 @@ func (s *LayeredStore) TotalSearchDbConnections() int {
 	return s.DatabaseLayer.TotalSearchDbConnections()
 }
 
+func (s *LayeredStore) IsMasterHealthy() bool {
+	return s.DatabaseLayer.IsMasterHealthy()
+}
+
+func (s *LayeredStore) IsReadOnly() bool {
+	return s.DatabaseLayer.IsReadOnly()
+}
+
+func (s *LayeredStore) SetReadOnly(readOnly bool) {
+	s.DatabaseLayer.SetReadOnly(readOnly)
+}
+
+func (s *LayeredStore) IsFullUnicodeSupported() bool {
+	return s.DatabaseLayer.IsFullUnicodeSupported()
+}
+
+type webSocketOutboxEventContextKey struct{}
+
+// WithWebSocketOutboxEvent returns a context that has the supplier that makes a change also save the websocket event
+// for it in the same transaction.
+func WithWebSocketOutboxEvent(ctx context.Context, event *model.WebSocketOutboxEvent) context.Context {
+	return context.WithValue(ctx, webSocketOutboxEventContextKey{}, event)
+}
+
+// WebSocketOutboxEventFromContext returns the event added to the context by WithWebSocketOutboxEvent, or nil if there
+// isn't one.
+func WebSocketOutboxEventFromContext(ctx context.Context) *model.WebSocketOutboxEvent {
+	event, _ := ctx.Value(webSocketOutboxEventContextKey{}).(*model.WebSocketOutboxEvent)
+	return event
+}
+
 type LayeredReactionStore struct {
 	*LayeredStore
 }
@@ -201,18 +303,42 @@ func (s *LayeredReactionStore) Save(reaction *model.Reaction) StoreChannel {
 	})
 }
 
+func (s *LayeredReactionStore) SaveWithWebSocketEvent(reaction *model.Reaction, event *model.WebSocketOutboxEvent) StoreChannel {
+	return s.RunQuery(func(supplier LayeredStoreSupplier) *LayeredStoreSupplierResult {
+		return supplier.ReactionSave(WithWebSocketOutboxEvent(s.TmpContext, event), reaction)
+	})
+}
+
 func (s *LayeredReactionStore) Delete(reaction *model.Reaction) StoreChannel {
 	return s.RunQuery(func(supplier LayeredStoreSupplier) *LayeredStoreSupplierResult {
 		return supplier.ReactionDelete(s.TmpContext, reaction)
 	})
 }
 
+func (s *LayeredReactionStore) DeleteWithWebSocketEvent(reaction *model.Reaction, event *model.WebSocketOutboxEvent) StoreChannel {
+	return s.RunQuery(func(supplier LayeredStoreSupplier) *LayeredStoreSupplierResult {
+		return supplier.ReactionDelete(WithWebSocketOutboxEvent(s.TmpContext, event), reaction)
+	})
+}
+
 func (s *LayeredReactionStore) GetForPost(postId string, allowFromCache bool) StoreChannel {
 	return s.RunQuery(func(supplier LayeredStoreSupplier) *LayeredStoreSupplierResult {
 		return supplier.ReactionGetForPost(s.TmpContext, postId)
 	})
 }
 
+func (s *LayeredReactionStore) GetForUser(userId string) StoreChannel {
+	return s.RunQuery(func(supplier LayeredStoreSupplier) *LayeredStoreSupplierResult {
+		return supplier.ReactionGetForUser(s.TmpContext, userId)
+	})
+}
+
+func (s *LayeredReactionStore) GetCountsForPosts(postIds []string, userId string) StoreChannel {
+	return s.RunQuery(func(supplier LayeredStoreSupplier) *LayeredStoreSupplierResult {
+		return supplier.ReactionGetCountsForPosts(s.TmpContext, postIds, userId)
+	})
+}
+
 func (s *LayeredReactionStore) DeleteAllWithEmojiName(emojiName string) StoreChannel {
 	return s.RunQuery(func(supplier LayeredStoreSupplier) *LayeredStoreSupplierResult {
 		return supplier.ReactionDeleteAllWithEmojiName(s.TmpContext, emojiName)
@@ -39,6 +39,14 @@ func (s *LocalCacheSupplier) ReactionGetForPost(ctx context.Context, postId stri
 	return result
 }
 
+func (s *LocalCacheSupplier) ReactionGetForUser(ctx context.Context, userId string, hints ...LayeredStoreHint) *LayeredStoreSupplierResult {
+	return s.Next().ReactionGetForUser(ctx, userId, hints...)
+}
+
+func (s *LocalCacheSupplier) ReactionGetCountsForPosts(ctx context.Context, postIds []string, userId string, hints ...LayeredStoreHint) *LayeredStoreSupplierResult {
+	return s.Next().ReactionGetCountsForPosts(ctx, postIds, userId, hints...)
+}
+
 func (s *LocalCacheSupplier) ReactionDeleteAllWithEmojiName(ctx context.Context, emojiName string, hints ...LayeredStoreHint) *LayeredStoreSupplierResult {
 	// This could be improved. Right now we just clear the whole
 	// cache because we don't have a way find what post Ids have this emoji name.
@@ -45,6 +45,14 @@ func (s *RedisSupplier) ReactionGetForPost(ctx context.Context, postId string, h
 	return result
 }
 
+func (s *RedisSupplier) ReactionGetForUser(ctx context.Context, userId string, hints ...LayeredStoreHint) *LayeredStoreSupplierResult {
+	return s.Next().ReactionGetForUser(ctx, userId, hints...)
+}
+
+func (s *RedisSupplier) ReactionGetCountsForPosts(ctx context.Context, postIds []string, userId string, hints ...LayeredStoreHint) *LayeredStoreSupplierResult {
+	return s.Next().ReactionGetCountsForPosts(ctx, postIds, userId, hints...)
+}
+
 func (s *RedisSupplier) ReactionDeleteAllWithEmojiName(ctx context.Context, emojiName string, hints ...LayeredStoreHint) *LayeredStoreSupplierResult {
 	// Ignoring this. It's probably OK to have the emoji slowly expire from Redis.
 	return s.Next().ReactionDeleteAllWithEmojiName(ctx, emojiName, hints...)
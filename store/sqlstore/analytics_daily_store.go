@@ -0,0 +1,266 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+type SqlAnalyticsDailyStore struct {
+	SqlStore
+}
+
+func NewSqlAnalyticsDailyStore(sqlStore SqlStore) store.AnalyticsDailyStore {
+	s := &SqlAnalyticsDailyStore{
+		SqlStore: sqlStore,
+	}
+
+	for _, db := range sqlStore.GetAllConns() {
+		table := db.AddTableWithName(model.AnalyticsDaily{}, "AnalyticsDaily").SetKeys(false, "Day", "TeamId", "ChannelId", "Metric")
+		table.ColMap("Day").SetMaxSize(10)
+		table.ColMap("TeamId").SetMaxSize(26)
+		table.ColMap("ChannelId").SetMaxSize(26)
+		table.ColMap("Metric").SetMaxSize(32)
+
+		tableUser := db.AddTableWithName(model.AnalyticsUserDaily{}, "AnalyticsUserDaily").SetKeys(false, "Day", "UserId")
+		tableUser.ColMap("Day").SetMaxSize(10)
+		tableUser.ColMap("UserId").SetMaxSize(26)
+	}
+
+	return s
+}
+
+func (s SqlAnalyticsDailyStore) CreateIndexesIfNotExists() {
+	s.CreateIndexIfNotExists("idx_analyticsdaily_team_id_channel_id", "AnalyticsDaily", "TeamId, ChannelId")
+}
+
+// analyticsDailyRollupQueries compute the metrics of a day. Only public and private channels are rolled up
+// individually, while the system wide rows, which are always written so that a rolled up day can be told
+// apart from one that hasn't been, also cover direct and group messages.
+var analyticsDailyRollupQueries = []string{
+	`INSERT INTO AnalyticsDaily (Day, TeamId, ChannelId, Metric, Value)
+		SELECT :Day, Channels.TeamId, Posts.ChannelId, '` + model.ANALYTICS_METRIC_POSTS + `', COUNT(Posts.Id)
+		FROM Posts
+		INNER JOIN Channels ON Posts.ChannelId = Channels.Id
+		WHERE Channels.Type IN ('O', 'P')
+		AND Posts.CreateAt >= :StartTime AND Posts.CreateAt <= :EndTime
+		GROUP BY Channels.TeamId, Posts.ChannelId`,
+	`INSERT INTO AnalyticsDaily (Day, TeamId, ChannelId, Metric, Value)
+		SELECT :Day, Channels.TeamId, Posts.ChannelId, '` + model.ANALYTICS_METRIC_ACTIVE_USERS + `', COUNT(DISTINCT Posts.UserId)
+		FROM Posts
+		INNER JOIN Channels ON Posts.ChannelId = Channels.Id
+		WHERE Channels.Type IN ('O', 'P')
+		AND Posts.CreateAt >= :StartTime AND Posts.CreateAt <= :EndTime
+		GROUP BY Channels.TeamId, Posts.ChannelId`,
+	`INSERT INTO AnalyticsDaily (Day, TeamId, ChannelId, Metric, Value)
+		SELECT :Day, Channels.TeamId, ChannelMemberHistory.ChannelId, '` + model.ANALYTICS_METRIC_NEW_MEMBERS + `', COUNT(DISTINCT ChannelMemberHistory.UserId)
+		FROM ChannelMemberHistory
+		INNER JOIN Channels ON ChannelMemberHistory.ChannelId = Channels.Id
+		WHERE Channels.Type IN ('O', 'P')
+		AND ChannelMemberHistory.JoinTime >= :StartTime AND ChannelMemberHistory.JoinTime <= :EndTime
+		GROUP BY Channels.TeamId, ChannelMemberHistory.ChannelId`,
+	`INSERT INTO AnalyticsDaily (Day, TeamId, ChannelId, Metric, Value)
+		SELECT :Day, Channels.TeamId, '', '` + model.ANALYTICS_METRIC_POSTS + `', COUNT(Posts.Id)
+		FROM Posts
+		INNER JOIN Channels ON Posts.ChannelId = Channels.Id
+		WHERE Channels.TeamId != ''
+		AND Posts.CreateAt >= :StartTime AND Posts.CreateAt <= :EndTime
+		GROUP BY Channels.TeamId`,
+	`INSERT INTO AnalyticsDaily (Day, TeamId, ChannelId, Metric, Value)
+		SELECT :Day, Channels.TeamId, '', '` + model.ANALYTICS_METRIC_ACTIVE_USERS + `', COUNT(DISTINCT Posts.UserId)
+		FROM Posts
+		INNER JOIN Channels ON Posts.ChannelId = Channels.Id
+		WHERE Channels.TeamId != ''
+		AND Posts.CreateAt >= :StartTime AND Posts.CreateAt <= :EndTime
+		GROUP BY Channels.TeamId`,
+	// every member of a team is a member of its town square, so joining it is joining the team
+	`INSERT INTO AnalyticsDaily (Day, TeamId, ChannelId, Metric, Value)
+		SELECT :Day, Channels.TeamId, '', '` + model.ANALYTICS_METRIC_NEW_MEMBERS + `', COUNT(DISTINCT ChannelMemberHistory.UserId)
+		FROM ChannelMemberHistory
+		INNER JOIN Channels ON ChannelMemberHistory.ChannelId = Channels.Id
+		WHERE Channels.Name = '` + model.DEFAULT_CHANNEL + `'
+		AND ChannelMemberHistory.JoinTime >= :StartTime AND ChannelMemberHistory.JoinTime <= :EndTime
+		GROUP BY Channels.TeamId`,
+	`INSERT INTO AnalyticsDaily (Day, TeamId, ChannelId, Metric, Value)
+		SELECT :Day, '', '', '` + model.ANALYTICS_METRIC_POSTS + `', COUNT(Posts.Id)
+		FROM Posts
+		WHERE Posts.CreateAt >= :StartTime AND Posts.CreateAt <= :EndTime`,
+	`INSERT INTO AnalyticsDaily (Day, TeamId, ChannelId, Metric, Value)
+		SELECT :Day, '', '', '` + model.ANALYTICS_METRIC_ACTIVE_USERS + `', COUNT(DISTINCT Posts.UserId)
+		FROM Posts
+		WHERE Posts.CreateAt >= :StartTime AND Posts.CreateAt <= :EndTime`,
+	`INSERT INTO AnalyticsDaily (Day, TeamId, ChannelId, Metric, Value)
+		SELECT :Day, '', '', '` + model.ANALYTICS_METRIC_NEW_MEMBERS + `', COUNT(Users.Id)
+		FROM Users
+		WHERE Users.CreateAt >= :StartTime AND Users.CreateAt <= :EndTime`,
+	// a row for each user that posted, reacted or joined a channel, where system messages aren't activity and
+	// only public and private channels count as joined
+	`INSERT INTO AnalyticsUserDaily (Day, UserId, Posts, Replies, Reactions, ChannelsJoined, LastActivityAt)
+		SELECT :Day, Activity.UserId, SUM(Activity.Posts), SUM(Activity.Replies), SUM(Activity.Reactions), SUM(Activity.ChannelsJoined), MAX(Activity.LastActivityAt)
+		FROM (
+			SELECT Posts.UserId AS UserId, COUNT(Posts.Id) AS Posts, SUM(CASE WHEN Posts.RootId != '' THEN 1 ELSE 0 END) AS Replies, 0 AS Reactions, 0 AS ChannelsJoined, MAX(Posts.CreateAt) AS LastActivityAt
+			FROM Posts
+			WHERE Posts.Type NOT LIKE '` + model.POST_SYSTEM_MESSAGE_PREFIX + `%'
+			AND Posts.CreateAt >= :StartTime AND Posts.CreateAt <= :EndTime
+			GROUP BY Posts.UserId
+			UNION ALL
+			SELECT Reactions.UserId AS UserId, 0 AS Posts, 0 AS Replies, COUNT(*) AS Reactions, 0 AS ChannelsJoined, MAX(Reactions.CreateAt) AS LastActivityAt
+			FROM Reactions
+			WHERE Reactions.CreateAt >= :StartTime AND Reactions.CreateAt <= :EndTime
+			GROUP BY Reactions.UserId
+			UNION ALL
+			SELECT ChannelMemberHistory.UserId AS UserId, 0 AS Posts, 0 AS Replies, 0 AS Reactions, COUNT(DISTINCT ChannelMemberHistory.ChannelId) AS ChannelsJoined, MAX(ChannelMemberHistory.JoinTime) AS LastActivityAt
+			FROM ChannelMemberHistory
+			INNER JOIN Channels ON ChannelMemberHistory.ChannelId = Channels.Id
+			WHERE Channels.Type IN ('O', 'P')
+			AND ChannelMemberHistory.JoinTime >= :StartTime AND ChannelMemberHistory.JoinTime <= :EndTime
+			GROUP BY ChannelMemberHistory.UserId
+		) Activity
+		GROUP BY Activity.UserId`,
+}
+
+// RollupDay replaces the metrics and the user activity of the day, which spans from startTime to endTime, with
+// ones computed from the posts, reactions and memberships of that day.
+func (s SqlAnalyticsDailyStore) RollupDay(day string, startTime int64, endTime int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		transaction, err := s.GetMaster().Begin()
+		if err != nil {
+			result.Err = model.NewAppError("SqlAnalyticsDailyStore.RollupDay", "store.sql_analytics_daily.rollup_day.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		params := map[string]interface{}{"Day": day, "StartTime": startTime, "EndTime": endTime}
+
+		queries := append([]string{
+			"DELETE FROM AnalyticsDaily WHERE Day = :Day",
+			"DELETE FROM AnalyticsUserDaily WHERE Day = :Day",
+		}, analyticsDailyRollupQueries...)
+
+		for _, query := range queries {
+			if result.Err != nil {
+				break
+			}
+
+			if _, err := transaction.Exec(query, params); err != nil {
+				result.Err = model.NewAppError("SqlAnalyticsDailyStore.RollupDay", "store.sql_analytics_daily.rollup_day.app_error", nil, "day="+day+", "+err.Error(), http.StatusInternalServerError)
+			}
+		}
+
+		if result.Err == nil {
+			if err := transaction.Commit(); err != nil {
+				// don't need to rollback here since the transaction is already closed
+				result.Err = model.NewAppError("SqlAnalyticsDailyStore.RollupDay", "store.sql_analytics_daily.rollup_day.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			}
+		} else {
+			if err := transaction.Rollback(); err != nil {
+				result.Err = model.NewAppError("SqlAnalyticsDailyStore.RollupDay", "store.sql_analytics_daily.rollup_day.rollback_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			}
+		}
+	})
+}
+
+// GetSeries returns the values of the metric between fromDay and toDay, inclusive, ordered by day. Days on
+// which there was nothing to count have no value. An empty teamId and channelId select the system wide
+// values, and an empty channelId selects the values for the whole team.
+func (s SqlAnalyticsDailyStore) GetSeries(teamId string, channelId string, metric string, fromDay string, toDay string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var series []*model.AnalyticsDaily
+		if _, err := s.GetReplica().Select(&series,
+			`SELECT
+				*
+			FROM
+				AnalyticsDaily
+			WHERE
+				TeamId = :TeamId
+				AND ChannelId = :ChannelId
+				AND Metric = :Metric
+				AND Day >= :FromDay
+				AND Day <= :ToDay
+			ORDER BY
+				Day ASC`,
+			map[string]interface{}{"TeamId": teamId, "ChannelId": channelId, "Metric": metric, "FromDay": fromDay, "ToDay": toDay}); err != nil {
+			result.Err = model.NewAppError("SqlAnalyticsDailyStore.GetSeries", "store.sql_analytics_daily.get_series.app_error", nil, "team_id="+teamId+", channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = series
+		}
+	})
+}
+
+// GetLatestDay returns the most recent day that has been rolled up, or an empty string if none has been.
+func (s SqlAnalyticsDailyStore) GetLatestDay() store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if latest, err := s.GetReplica().SelectNullStr("SELECT MAX(Day) FROM AnalyticsDaily WHERE TeamId = '' AND ChannelId = ''"); err != nil {
+			result.Err = model.NewAppError("SqlAnalyticsDailyStore.GetLatestDay", "store.sql_analytics_daily.get_latest_day.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = latest.String
+		}
+	})
+}
+
+// GetUserActivity returns the activity of each user between fromDay and toDay, inclusive, ordered by the
+// number of posts and then by user id. Users whose posts and reactions add up to less than minActivity are
+// left out.
+func (s SqlAnalyticsDailyStore) GetUserActivity(fromDay string, toDay string, minActivity int64, offset int, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var activity []*model.AnalyticsUserActivity
+		if _, err := s.GetReplica().Select(&activity,
+			`SELECT
+				AnalyticsUserDaily.UserId AS UserId,
+				Users.Username AS Username,
+				SUM(AnalyticsUserDaily.Posts) AS Posts,
+				SUM(AnalyticsUserDaily.Replies) AS Replies,
+				SUM(AnalyticsUserDaily.Reactions) AS Reactions,
+				SUM(AnalyticsUserDaily.ChannelsJoined) AS ChannelsJoined,
+				MAX(AnalyticsUserDaily.LastActivityAt) AS LastActivityAt
+			FROM
+				AnalyticsUserDaily
+				INNER JOIN Users ON AnalyticsUserDaily.UserId = Users.Id
+			WHERE
+				AnalyticsUserDaily.Day >= :FromDay
+				AND AnalyticsUserDaily.Day <= :ToDay
+			GROUP BY
+				AnalyticsUserDaily.UserId, Users.Username
+			HAVING
+				SUM(AnalyticsUserDaily.Posts) + SUM(AnalyticsUserDaily.Reactions) >= :MinActivity
+			ORDER BY
+				Posts DESC, UserId ASC
+			LIMIT :Limit OFFSET :Offset`,
+			map[string]interface{}{"FromDay": fromDay, "ToDay": toDay, "MinActivity": minActivity, "Offset": offset, "Limit": limit}); err != nil {
+			result.Err = model.NewAppError("SqlAnalyticsDailyStore.GetUserActivity", "store.sql_analytics_daily.get_user_activity.app_error", nil, "from="+fromDay+", to="+toDay+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = activity
+		}
+	})
+}
+
+// PermanentDeleteBefore deletes the metrics and the user activity of the days before the given one and returns
+// how many rows were deleted.
+func (s SqlAnalyticsDailyStore) PermanentDeleteBefore(day string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var deleted int64
+		for _, query := range []string{
+			"DELETE FROM AnalyticsDaily WHERE Day < :Day",
+			"DELETE FROM AnalyticsUserDaily WHERE Day < :Day",
+		} {
+			sqlResult, err := s.GetMaster().Exec(query, map[string]interface{}{"Day": day})
+			if err != nil {
+				result.Err = model.NewAppError("SqlAnalyticsDailyStore.PermanentDeleteBefore", "store.sql_analytics_daily.permanent_delete_before.app_error", nil, "day="+day+", "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			rowsAffected, err := sqlResult.RowsAffected()
+			if err != nil {
+				result.Err = model.NewAppError("SqlAnalyticsDailyStore.PermanentDeleteBefore", "store.sql_analytics_daily.permanent_delete_before.app_error", nil, "day="+day+", "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			deleted += rowsAffected
+		}
+
+		result.Data = deleted
+	})
+}
@@ -32,6 +32,7 @@ func NewSqlAuditStore(sqlStore SqlStore) store.AuditStore {
 
 func (s SqlAuditStore) CreateIndexesIfNotExists() {
 	s.CreateIndexIfNotExists("idx_audits_user_id", "Audits", "UserId")
+	s.CreateIndexIfNotExists("idx_audits_create_at", "Audits", "CreateAt")
 }
 
 func (s SqlAuditStore) Save(audit *model.Audit) store.StoreChannel {
@@ -102,3 +103,26 @@ func (s SqlAuditStore) PermanentDeleteBatch(endTime int64, limit int64) store.St
 		}
 	})
 }
+
+// GetCountBefore returns how many audits were created before the given time.
+func (s SqlAuditStore) GetCountBefore(endTime int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if count, err := s.GetReplica().SelectInt("SELECT COUNT(*) FROM Audits WHERE CreateAt < :EndTime", map[string]interface{}{"EndTime": endTime}); err != nil {
+			result.Err = model.NewAppError("SqlAuditStore.GetCountBefore", "store.sql_audit.get_count_before.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = count
+		}
+	})
+}
+
+// GetMaxRowsCutoff returns the time that the oldest of the newest maxRows audits was created at, so that deleting the
+// audits created before it keeps at least that many. It returns 0 if there aren't more than maxRows audits.
+func (s SqlAuditStore) GetMaxRowsCutoff(maxRows int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if cutoff, err := s.GetReplica().SelectInt("SELECT CreateAt FROM Audits ORDER BY CreateAt DESC LIMIT 1 OFFSET :Offset", map[string]interface{}{"Offset": maxRows - 1}); err != nil {
+			result.Err = model.NewAppError("SqlAuditStore.GetMaxRowsCutoff", "store.sql_audit.get_max_rows_cutoff.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = cutoff
+		}
+	})
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+type SqlBlockedDomainStore struct {
+	SqlStore
+}
+
+func NewSqlBlockedDomainStore(sqlStore SqlStore) store.BlockedDomainStore {
+	s := &SqlBlockedDomainStore{sqlStore}
+
+	for _, db := range sqlStore.GetAllConns() {
+		table := db.AddTableWithName(model.BlockedDomain{}, "BlockedDomains").SetKeys(false, "Domain")
+		table.ColMap("Domain").SetMaxSize(model.BLOCKED_DOMAIN_MAX_LENGTH)
+		table.ColMap("CreatorId").SetMaxSize(26)
+	}
+
+	return s
+}
+
+func (s SqlBlockedDomainStore) Save(domain *model.BlockedDomain) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		domain.PreSave()
+
+		if result.Err = domain.IsValid(); result.Err != nil {
+			return
+		}
+
+		if err := s.GetMaster().Insert(domain); err != nil {
+			if IsUniqueConstraintError(err, []string{"PRIMARY", "blockeddomains_pkey"}) {
+				result.Err = model.NewAppError("SqlBlockedDomainStore.Save", "store.sql_blocked_domain.save.exists.app_error", map[string]interface{}{"Domain": domain.Domain}, err.Error(), http.StatusBadRequest)
+			} else {
+				result.Err = model.NewAppError("SqlBlockedDomainStore.Save", "store.sql_blocked_domain.save.app_error", nil, "domain="+domain.Domain+", "+err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		result.Data = domain
+	})
+}
+
+func (s SqlBlockedDomainStore) GetAll() store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		domains := []*model.BlockedDomain{}
+
+		if _, err := s.GetReplica().Select(&domains, "SELECT * FROM BlockedDomains ORDER BY Domain"); err != nil {
+			result.Err = model.NewAppError("SqlBlockedDomainStore.GetAll", "store.sql_blocked_domain.get_all.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = domains
+	})
+}
+
+func (s SqlBlockedDomainStore) Delete(domain string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if _, err := s.GetMaster().Exec("DELETE FROM BlockedDomains WHERE Domain = :Domain", map[string]interface{}{"Domain": domain}); err != nil {
+			result.Err = model.NewAppError("SqlBlockedDomainStore.Delete", "store.sql_blocked_domain.delete.app_error", nil, "domain="+domain+", "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
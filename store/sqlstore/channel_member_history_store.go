@@ -28,17 +28,21 @@ func NewSqlChannelMemberHistoryStore(sqlStore SqlStore) store.ChannelMemberHisto
 		table.ColMap("ChannelId").SetMaxSize(26)
 		table.ColMap("UserId").SetMaxSize(26)
 		table.ColMap("JoinTime").SetNotNull(true)
+		table.ColMap("JoinActorId").SetMaxSize(26)
+		table.ColMap("LeaveActorId").SetMaxSize(26)
+		table.ColMap("LeaveReason").SetMaxSize(32)
 	}
 
 	return s
 }
 
-func (s SqlChannelMemberHistoryStore) LogJoinEvent(userId string, channelId string, joinTime int64) store.StoreChannel {
+func (s SqlChannelMemberHistoryStore) LogJoinEvent(userId string, channelId string, joinTime int64, actorId string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		channelMemberHistory := &model.ChannelMemberHistory{
-			UserId:    userId,
-			ChannelId: channelId,
-			JoinTime:  joinTime,
+			UserId:      userId,
+			ChannelId:   channelId,
+			JoinTime:    joinTime,
+			JoinActorId: actorId,
 		}
 
 		if err := s.GetMaster().Insert(channelMemberHistory); err != nil {
@@ -47,16 +51,18 @@ func (s SqlChannelMemberHistoryStore) LogJoinEvent(userId string, channelId stri
 	})
 }
 
-func (s SqlChannelMemberHistoryStore) LogLeaveEvent(userId string, channelId string, leaveTime int64) store.StoreChannel {
+func (s SqlChannelMemberHistoryStore) LogLeaveEvent(userId string, channelId string, leaveTime int64, actorId string, reason string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		query := `
 			UPDATE ChannelMemberHistory
-			SET LeaveTime = :LeaveTime
+			SET LeaveTime = :LeaveTime,
+				LeaveActorId = :LeaveActorId,
+				LeaveReason = :LeaveReason
 			WHERE UserId = :UserId
 			AND ChannelId = :ChannelId
 			AND LeaveTime IS NULL`
 
-		params := map[string]interface{}{"UserId": userId, "ChannelId": channelId, "LeaveTime": leaveTime}
+		params := map[string]interface{}{"UserId": userId, "ChannelId": channelId, "LeaveTime": leaveTime, "LeaveActorId": actorId, "LeaveReason": reason}
 		if sqlResult, err := s.GetMaster().Exec(query, params); err != nil {
 			result.Err = model.NewAppError("SqlChannelMemberHistoryStore.LogLeaveEvent", "store.sql_channel_member_history.log_leave_event.update_error", params, err.Error(), http.StatusInternalServerError)
 		} else if rows, err := sqlResult.RowsAffected(); err == nil && rows != 1 {
@@ -91,6 +97,46 @@ func (s SqlChannelMemberHistoryStore) GetUsersInChannelDuring(startTime int64, e
 	})
 }
 
+// GetChannelHistory returns the memberships of the channel that were started or ended between since and until,
+// most recently started first.
+func (s SqlChannelMemberHistoryStore) GetChannelHistory(channelId string, since int64, until int64, offset int, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		query := `
+			SELECT
+				cmh.*,
+				u.Email,
+				u.Username
+			FROM ChannelMemberHistory cmh
+			INNER JOIN Users u ON cmh.UserId = u.Id
+			WHERE cmh.ChannelId = :ChannelId
+			AND ((cmh.JoinTime >= :Since AND cmh.JoinTime <= :Until)
+				OR (cmh.LeaveTime >= :Since AND cmh.LeaveTime <= :Until))
+			ORDER BY cmh.JoinTime DESC, cmh.UserId ASC
+			LIMIT :Limit OFFSET :Offset`
+
+		params := map[string]interface{}{"ChannelId": channelId, "Since": since, "Until": until, "Limit": limit, "Offset": offset}
+		var histories []*model.ChannelMemberHistoryResult
+		if _, err := s.GetReplica().Select(&histories, query, params); err != nil {
+			result.Err = model.NewAppError("SqlChannelMemberHistoryStore.GetChannelHistory", "store.sql_channel_member_history.get_channel_history.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = histories
+		}
+	})
+}
+
+// GetLastLeaveTime returns when the user last left or was removed from the channel, or 0 if they never have been.
+func (s SqlChannelMemberHistoryStore) GetLastLeaveTime(userId string, channelId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		query := "SELECT MAX(LeaveTime) FROM ChannelMemberHistory WHERE UserId = :UserId AND ChannelId = :ChannelId"
+
+		if leaveTime, err := s.GetReplica().SelectNullInt(query, map[string]interface{}{"UserId": userId, "ChannelId": channelId}); err != nil {
+			result.Err = model.NewAppError("SqlChannelMemberHistoryStore.GetLastLeaveTime", "store.sql_channel_member_history.get_last_leave_time.app_error", nil, "user_id="+userId+", channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = leaveTime.Int64
+		}
+	})
+}
+
 func (s SqlChannelMemberHistoryStore) hasDataAtOrBefore(time int64) (bool, error) {
 	type NullableCountResult struct {
 		Min sql.NullInt64
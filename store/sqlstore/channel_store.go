@@ -26,43 +26,44 @@ const (
 	ALL_CHANNEL_MEMBERS_NOTIFY_PROPS_FOR_CHANNEL_CACHE_SIZE = model.SESSION_CACHE_SIZE
 	ALL_CHANNEL_MEMBERS_NOTIFY_PROPS_FOR_CHANNEL_CACHE_SEC  = 1800 // 30 mins
 
-	CHANNEL_MEMBERS_COUNTS_CACHE_SIZE = model.CHANNEL_CACHE_SIZE
-	CHANNEL_MEMBERS_COUNTS_CACHE_SEC  = 1800 // 30 mins
-
 	CHANNEL_CACHE_SEC = 900 // 15 mins
 )
 
 type SqlChannelStore struct {
 	SqlStore
 	metrics einterfaces.MetricsInterface
+
+	channelByNameCache       *utils.Cache
+	channelMemberCountsCache *utils.Cache
+	channelModerationsCache  *utils.Cache
 }
 
-var channelMemberCountsCache = utils.NewLru(CHANNEL_MEMBERS_COUNTS_CACHE_SIZE)
 var allChannelMembersForUserCache = utils.NewLru(ALL_CHANNEL_MEMBERS_FOR_USER_CACHE_SIZE)
 var allChannelMembersNotifyPropsForChannelCache = utils.NewLru(ALL_CHANNEL_MEMBERS_NOTIFY_PROPS_FOR_CHANNEL_CACHE_SIZE)
 var channelCache = utils.NewLru(model.CHANNEL_CACHE_SIZE)
-var channelByNameCache = utils.NewLru(model.CHANNEL_CACHE_SIZE)
 
 func (s SqlChannelStore) ClearCaches() {
-	channelMemberCountsCache.Purge()
+	s.GetCacheProvider().InvalidateSkipClusterSend(model.CACHE_CHANNEL_MEMBER_COUNTS, utils.CACHE_PURGE_KEY)
 	allChannelMembersForUserCache.Purge()
 	allChannelMembersNotifyPropsForChannelCache.Purge()
 	channelCache.Purge()
-	channelByNameCache.Purge()
+	s.GetCacheProvider().InvalidateSkipClusterSend(model.CACHE_CHANNEL_BY_NAME, utils.CACHE_PURGE_KEY)
+	s.GetCacheProvider().InvalidateSkipClusterSend(model.CACHE_CHANNEL_MODERATIONS, utils.CACHE_PURGE_KEY)
 
 	if s.metrics != nil {
-		s.metrics.IncrementMemCacheInvalidationCounter("Channel Member Counts - Purge")
 		s.metrics.IncrementMemCacheInvalidationCounter("All Channel Members for User - Purge")
 		s.metrics.IncrementMemCacheInvalidationCounter("All Channel Members Notify Props for Channel - Purge")
 		s.metrics.IncrementMemCacheInvalidationCounter("Channel - Purge")
-		s.metrics.IncrementMemCacheInvalidationCounter("Channel By Name - Purge")
 	}
 }
 
 func NewSqlChannelStore(sqlStore SqlStore, metrics einterfaces.MetricsInterface) store.ChannelStore {
 	s := &SqlChannelStore{
-		SqlStore: sqlStore,
-		metrics:  metrics,
+		SqlStore:                 sqlStore,
+		metrics:                  metrics,
+		channelByNameCache:       sqlStore.GetCacheProvider().GetCache(model.CACHE_CHANNEL_BY_NAME),
+		channelMemberCountsCache: sqlStore.GetCacheProvider().GetCache(model.CACHE_CHANNEL_MEMBER_COUNTS),
+		channelModerationsCache:  sqlStore.GetCacheProvider().GetCache(model.CACHE_CHANNEL_MODERATIONS),
 	}
 
 	for _, db := range sqlStore.GetAllConns() {
@@ -73,15 +74,41 @@ func NewSqlChannelStore(sqlStore SqlStore, metrics einterfaces.MetricsInterface)
 		table.ColMap("DisplayName").SetMaxSize(64)
 		table.ColMap("Name").SetMaxSize(64)
 		table.SetUniqueTogether("Name", "TeamId")
-		table.ColMap("Header").SetMaxSize(1024)
-		table.ColMap("Purpose").SetMaxSize(250)
+		table.ColMap("Header").SetMaxSize(model.CHANNEL_HEADER_MAX_RUNES)
+		table.ColMap("Purpose").SetMaxSize(model.CHANNEL_PURPOSE_MAX_RUNES)
 		table.ColMap("CreatorId").SetMaxSize(26)
+		table.ColMap("JoinLeaveMessages").SetMaxSize(8)
+		table.ColMap("FeedKey").SetMaxSize(26)
+		table.ColMap("DisabledCommandTriggers").SetMaxSize(1024)
+		table.ColMap("IconEmojiName").SetMaxSize(model.EMOJI_NAME_MAX_LENGTH)
 
 		tablem := db.AddTableWithName(model.ChannelMember{}, "ChannelMembers").SetKeys(false, "ChannelId", "UserId")
 		tablem.ColMap("ChannelId").SetMaxSize(26)
 		tablem.ColMap("UserId").SetMaxSize(26)
 		tablem.ColMap("Roles").SetMaxSize(64)
 		tablem.ColMap("NotifyProps").SetMaxSize(2000)
+		tablem.ColMap("LastReadPostId").SetMaxSize(26)
+
+		tablec := db.AddTableWithName(model.SidebarCategory{}, "SidebarCategories").SetKeys(false, "Id")
+		tablec.ColMap("Id").SetMaxSize(26)
+		tablec.ColMap("UserId").SetMaxSize(26)
+		tablec.ColMap("TeamId").SetMaxSize(26)
+		tablec.ColMap("Type").SetMaxSize(32)
+		tablec.ColMap("DisplayName").SetMaxSize(64)
+
+		tables := db.AddTableWithName(model.SidebarChannel{}, "SidebarChannels").SetKeys(false, "ChannelId", "UserId", "CategoryId")
+		tables.ColMap("ChannelId").SetMaxSize(26)
+		tables.ColMap("UserId").SetMaxSize(26)
+		tables.ColMap("CategoryId").SetMaxSize(26)
+
+		tablemod := db.AddTableWithName(channelModeration{}, "ChannelModerations").SetKeys(false, "ChannelId", "Name")
+		tablemod.ColMap("ChannelId").SetMaxSize(26)
+		tablemod.ColMap("Name").SetMaxSize(64)
+
+		tablenh := db.AddTableWithName(model.ChannelNameHistory{}, "ChannelNameHistory").SetKeys(false, "TeamId", "Name")
+		tablenh.ColMap("TeamId").SetMaxSize(26)
+		tablenh.ColMap("Name").SetMaxSize(64)
+		tablenh.ColMap("ChannelId").SetMaxSize(26)
 	}
 
 	return s
@@ -93,6 +120,7 @@ func (s SqlChannelStore) CreateIndexesIfNotExists() {
 	s.CreateIndexIfNotExists("idx_channels_update_at", "Channels", "UpdateAt")
 	s.CreateIndexIfNotExists("idx_channels_create_at", "Channels", "CreateAt")
 	s.CreateIndexIfNotExists("idx_channels_delete_at", "Channels", "DeleteAt")
+	s.CreateIndexIfNotExists("idx_channels_permanent_delete_at", "Channels", "PermanentDeleteAt")
 
 	if s.DriverName() == model.DATABASE_DRIVER_POSTGRES {
 		s.CreateIndexIfNotExists("idx_channels_name_lower", "Channels", "lower(Name)")
@@ -102,6 +130,11 @@ func (s SqlChannelStore) CreateIndexesIfNotExists() {
 	s.CreateIndexIfNotExists("idx_channelmembers_channel_id", "ChannelMembers", "ChannelId")
 	s.CreateIndexIfNotExists("idx_channelmembers_user_id", "ChannelMembers", "UserId")
 
+	s.CreateIndexIfNotExists("idx_sidebarcategories_user_id", "SidebarCategories", "UserId")
+	s.CreateIndexIfNotExists("idx_sidebarchannels_category_id", "SidebarChannels", "CategoryId")
+
+	s.CreateIndexIfNotExists("idx_channelnamehistory_channel_id", "ChannelNameHistory", "ChannelId")
+
 	s.CreateFullTextIndexIfNotExists("idx_channels_txt", "Channels", "Name, DisplayName")
 }
 
@@ -250,32 +283,88 @@ func (s SqlChannelStore) Update(channel *model.Channel) store.StoreChannel {
 			return
 		}
 
-		if count, err := s.GetMaster().Update(channel); err != nil {
-			if IsUniqueConstraintError(err, []string{"Name", "channels_name_teamid_key"}) {
-				dupChannel := model.Channel{}
-				s.GetReplica().SelectOne(&dupChannel, "SELECT * FROM Channels WHERE TeamId = :TeamId AND Name= :Name AND DeleteAt > 0", map[string]interface{}{"TeamId": channel.TeamId, "Name": channel.Name})
-				if dupChannel.DeleteAt > 0 {
-					result.Err = model.NewAppError("SqlChannelStore.Update", "store.sql_channel.update.previously.app_error", nil, "id="+channel.Id+", "+err.Error(), http.StatusBadRequest)
-				} else {
-					result.Err = model.NewAppError("SqlChannelStore.Update", "store.sql_channel.update.exists.app_error", nil, "id="+channel.Id+", "+err.Error(), http.StatusBadRequest)
-				}
-			} else {
-				result.Err = model.NewAppError("SqlChannelStore.Update", "store.sql_channel.update.updating.app_error", nil, "id="+channel.Id+", "+err.Error(), http.StatusInternalServerError)
-			}
-		} else if count != 1 {
-			result.Err = model.NewAppError("SqlChannelStore.Update", "store.sql_channel.update.app_error", nil, "id="+channel.Id, http.StatusInternalServerError)
-		} else {
-			result.Data = channel
+		if result.Err = s.updateChannel(s.GetMaster(), channel); result.Err != nil {
+			return
+		}
+
+		result.Data = channel
+	})
+}
+
+// UpdateWithWebSocketEvent updates the channel and saves the outbox event for the update in the same transaction.
+func (s SqlChannelStore) UpdateWithWebSocketEvent(channel *model.Channel, event *model.WebSocketOutboxEvent) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		channel.PreUpdate()
+
+		if result.Err = channel.IsValid(); result.Err != nil {
+			return
+		}
+
+		transaction, err := s.GetMaster().Begin()
+		if err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.UpdateWithWebSocketEvent", "store.sql_channel.update.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if result.Err = s.updateChannel(transaction, channel); result.Err != nil {
+			transaction.Rollback()
+			return
+		}
+
+		if err := saveWebSocketOutboxEvent(transaction, event); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlChannelStore.UpdateWithWebSocketEvent", "store.sql_channel.update.websocket_event.app_error", nil, "id="+channel.Id+", "+err.Error(), http.StatusInternalServerError)
+			return
 		}
+
+		if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.UpdateWithWebSocketEvent", "store.sql_channel.update.commit_transaction.app_error", nil, "id="+channel.Id+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = channel
 	})
 }
 
+// channelColumnUpdater is the database or a transaction that a channel is updated in.
+type channelColumnUpdater interface {
+	UpdateColumns(filter gorp.ColumnFilter, list ...interface{}) (int64, error)
+}
+
+func (s SqlChannelStore) updateChannel(updater channelColumnUpdater, channel *model.Channel) *model.AppError {
+	count, err := updater.UpdateColumns(excludeStoreManagedChannelColumns, channel)
+	if err != nil {
+		if IsUniqueConstraintError(err, []string{"Name", "channels_name_teamid_key"}) {
+			dupChannel := model.Channel{}
+			s.GetReplica().SelectOne(&dupChannel, "SELECT * FROM Channels WHERE TeamId = :TeamId AND Name= :Name AND DeleteAt > 0", map[string]interface{}{"TeamId": channel.TeamId, "Name": channel.Name})
+			if dupChannel.DeleteAt > 0 {
+				return model.NewAppError("SqlChannelStore.Update", "store.sql_channel.update.previously.app_error", nil, "id="+channel.Id+", "+err.Error(), http.StatusBadRequest)
+			}
+			return model.NewAppError("SqlChannelStore.Update", "store.sql_channel.update.exists.app_error", nil, "id="+channel.Id+", "+err.Error(), http.StatusBadRequest)
+		}
+		return model.NewAppError("SqlChannelStore.Update", "store.sql_channel.update.updating.app_error", nil, "id="+channel.Id+", "+err.Error(), http.StatusInternalServerError)
+	}
+
+	if count != 1 {
+		return model.NewAppError("SqlChannelStore.Update", "store.sql_channel.update.app_error", nil, "id="+channel.Id, http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+// excludeStoreManagedChannelColumns stops an update to a channel from overwriting its member and guest counts and
+// when it's due to be permanently deleted, since the channel being updated may have been read before members last
+// joined or left it or before it was marked for deletion.
+func excludeStoreManagedChannelColumns(col *gorp.ColumnMap) bool {
+	return col.ColumnName != "MemberCount" && col.ColumnName != "GuestCount" && col.ColumnName != "PermanentDeleteAt"
+}
+
 func (s SqlChannelStore) GetChannelUnread(channelId, userId string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		var unreadChannel model.ChannelUnread
 		err := s.GetReplica().SelectOne(&unreadChannel,
 			`SELECT
-				Channels.TeamId TeamId, Channels.Id ChannelId, (Channels.TotalMsgCount - ChannelMembers.MsgCount) MsgCount, ChannelMembers.MentionCount MentionCount, ChannelMembers.NotifyProps NotifyProps
+				Channels.TeamId TeamId, Channels.Id ChannelId, (Channels.TotalMsgCount - ChannelMembers.MsgCount) MsgCount, ChannelMembers.MentionCount MentionCount, ChannelMembers.LastViewedAt LastViewedAt, ChannelMembers.LastReadPostId LastReadPostId, ChannelMembers.NotifyProps NotifyProps
 			FROM
 				Channels, ChannelMembers
 			WHERE
@@ -296,6 +385,36 @@ func (s SqlChannelStore) GetChannelUnread(channelId, userId string) store.StoreC
 	})
 }
 
+// GetChannelUnreadsForUser gets the unread counts of every channel that the user is a member of on a team,
+// including direct and group message channels, or on any team if teamId is blank.
+func (s SqlChannelStore) GetChannelUnreadsForUser(teamId, userId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		teamQuery := ""
+		if teamId != "" {
+			teamQuery = "AND (TeamId = :TeamId OR TeamId = '')"
+		}
+
+		var data []*model.ChannelUnread
+		_, err := s.GetReplica().Select(&data,
+			`SELECT
+				Channels.TeamId TeamId, Channels.Id ChannelId, (Channels.TotalMsgCount - ChannelMembers.MsgCount) MsgCount, ChannelMembers.MentionCount MentionCount, ChannelMembers.LastViewedAt LastViewedAt, ChannelMembers.LastReadPostId LastReadPostId, ChannelMembers.NotifyProps NotifyProps
+			FROM
+				Channels, ChannelMembers
+			WHERE
+				Id = ChannelId
+                AND UserId = :UserId
+                AND DeleteAt = 0
+                `+teamQuery,
+			map[string]interface{}{"TeamId": teamId, "UserId": userId})
+
+		if err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.GetChannelUnreadsForUser", "store.sql_channel.get_unreads_for_user.app_error", nil, "teamId="+teamId+", userId="+userId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = data
+		}
+	})
+}
+
 func (s SqlChannelStore) InvalidateChannel(id string) {
 	channelCache.Remove(id)
 	if s.metrics != nil {
@@ -304,10 +423,7 @@ func (s SqlChannelStore) InvalidateChannel(id string) {
 }
 
 func (s SqlChannelStore) InvalidateChannelByName(teamId, name string) {
-	channelByNameCache.Remove(teamId + name)
-	if s.metrics != nil {
-		s.metrics.IncrementMemCacheInvalidationCounter("Channel by Name - Remove by TeamId and Name")
-	}
+	s.GetCacheProvider().InvalidateSkipClusterSend(model.CACHE_CHANNEL_BY_NAME, teamId+name)
 }
 
 func (s SqlChannelStore) Get(id string, allowFromCache bool) store.StoreChannel {
@@ -382,17 +498,160 @@ func (s SqlChannelStore) Restore(channelId string, time int64) store.StoreChanne
 	return s.SetDeleteAt(channelId, 0, time)
 }
 
+// SetDeleteAt archives or restores the channel. Either cancels its permanent deletion if it was marked for it.
 func (s SqlChannelStore) SetDeleteAt(channelId string, deleteAt int64, updateAt int64) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
-		_, err := s.GetMaster().Exec("Update Channels SET DeleteAt = :DeleteAt, UpdateAt = :UpdateAt WHERE Id = :ChannelId", map[string]interface{}{"DeleteAt": deleteAt, "UpdateAt": updateAt, "ChannelId": channelId})
+		_, err := s.GetMaster().Exec("Update Channels SET DeleteAt = :DeleteAt, PermanentDeleteAt = 0, UpdateAt = :UpdateAt WHERE Id = :ChannelId", map[string]interface{}{"DeleteAt": deleteAt, "UpdateAt": updateAt, "ChannelId": channelId})
 		if err != nil {
 			result.Err = model.NewAppError("SqlChannelStore.Delete", "store.sql_channel.delete.channel.app_error", nil, "id="+channelId+", err="+err.Error(), http.StatusInternalServerError)
 		}
 	})
 }
 
+// SetPermanentDeleteAt marks the archived channel to be permanently deleted at the given time, or unmarks it for 0.
+// Channels that aren't archived can't be marked.
+func (s SqlChannelStore) SetPermanentDeleteAt(channelId string, permanentDeleteAt int64, updateAt int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		props := map[string]interface{}{"PermanentDeleteAt": permanentDeleteAt, "UpdateAt": updateAt, "ChannelId": channelId}
+
+		count, err := s.GetMaster().SelectInt("SELECT COUNT(*) FROM Channels WHERE Id = :ChannelId AND DeleteAt != 0", props)
+		if err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.SetPermanentDeleteAt", "store.sql_channel.set_permanent_delete_at.app_error", nil, "id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		} else if count == 0 {
+			result.Err = model.NewAppError("SqlChannelStore.SetPermanentDeleteAt", "store.sql_channel.set_permanent_delete_at.not_archived.app_error", nil, "id="+channelId, http.StatusBadRequest)
+			return
+		}
+
+		if _, err := s.GetMaster().Exec("UPDATE Channels SET PermanentDeleteAt = :PermanentDeleteAt, UpdateAt = :UpdateAt WHERE Id = :ChannelId AND DeleteAt != 0", props); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.SetPermanentDeleteAt", "store.sql_channel.set_permanent_delete_at.app_error", nil, "id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// GetDueForPermanentDeletion returns up to limit of the channels that were marked to be permanently deleted at or
+// before the given time, with the ones that have been due the longest first.
+func (s SqlChannelStore) GetDueForPermanentDeletion(before int64, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var channels []*model.Channel
+		if _, err := s.GetMaster().Select(&channels, `
+			SELECT
+				*
+			FROM
+				Channels
+			WHERE
+				PermanentDeleteAt != 0
+				AND PermanentDeleteAt <= :Before
+			ORDER BY PermanentDeleteAt, Id
+			LIMIT :Limit`, map[string]interface{}{"Before": before, "Limit": limit}); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.GetDueForPermanentDeletion", "store.sql_channel.get_due_for_permanent_deletion.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = channels
+	})
+}
+
+// GetBatchForTeam returns up to limit of the team's channels, including archived ones, ordered by id.
+func (s SqlChannelStore) GetBatchForTeam(teamId string, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var channels []*model.Channel
+		if _, err := s.GetMaster().Select(&channels, "SELECT * FROM Channels WHERE TeamId = :TeamId ORDER BY Id LIMIT :Limit", map[string]interface{}{"TeamId": teamId, "Limit": limit}); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.GetBatchForTeam", "store.sql_channel.get_batch_for_team.app_error", nil, "team_id="+teamId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = channels
+	})
+}
+
+// ArchiveDirectChannelsForUser archives the user's direct channels that are active, returning them.
+func (s SqlChannelStore) ArchiveDirectChannelsForUser(userId string, deleteAt int64) store.StoreChannel {
+	return s.setDirectChannelsDeleteAt(userId, `
+		SELECT
+			Channels.*
+		FROM
+			Channels, ChannelMembers
+		WHERE
+			Channels.Id = ChannelMembers.ChannelId
+			AND ChannelMembers.UserId = :UserId
+			AND Channels.Type = 'D'
+			AND Channels.DeleteAt = 0`, deleteAt, deleteAt)
+}
+
+// RestoreDirectChannelsForUser restores the user's archived direct channels with other users who are active,
+// returning them. Channels that are marked to be permanently deleted are left archived.
+func (s SqlChannelStore) RestoreDirectChannelsForUser(userId string, updateAt int64) store.StoreChannel {
+	return s.setDirectChannelsDeleteAt(userId, `
+		SELECT
+			Channels.*
+		FROM
+			Channels, ChannelMembers
+		WHERE
+			Channels.Id = ChannelMembers.ChannelId
+			AND ChannelMembers.UserId = :UserId
+			AND Channels.Type = 'D'
+			AND Channels.DeleteAt > 0
+			AND Channels.PermanentDeleteAt = 0
+			AND NOT EXISTS (
+				SELECT
+					1
+				FROM
+					ChannelMembers Others, Users
+				WHERE
+					Others.ChannelId = Channels.Id
+					AND Others.UserId != :UserId
+					AND Users.Id = Others.UserId
+					AND Users.DeleteAt > 0
+			)`, 0, updateAt)
+}
+
+func (s SqlChannelStore) setDirectChannelsDeleteAt(userId string, query string, deleteAt int64, updateAt int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var channels []*model.Channel
+		if _, err := s.GetMaster().Select(&channels, query, map[string]interface{}{"UserId": userId}); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.SetDirectChannelsDeleteAt", "store.sql_channel.set_direct_channels_delete_at.get.app_error", nil, "user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if len(channels) == 0 {
+			result.Data = channels
+			return
+		}
+
+		props := map[string]interface{}{"DeleteAt": deleteAt, "UpdateAt": updateAt}
+		var keys []string
+		for i, channel := range channels {
+			key := fmt.Sprintf("ChannelId%v", i)
+			props[key] = channel.Id
+			keys = append(keys, ":"+key)
+
+			channel.DeleteAt = deleteAt
+			channel.UpdateAt = updateAt
+		}
+
+		if _, err := s.GetMaster().Exec("UPDATE Channels SET DeleteAt = :DeleteAt, UpdateAt = :UpdateAt WHERE Id IN ("+strings.Join(keys, ", ")+")", props); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.SetDirectChannelsDeleteAt", "store.sql_channel.set_direct_channels_delete_at.update.app_error", nil, "user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = channels
+	})
+}
+
 func (s SqlChannelStore) PermanentDeleteByTeam(teamId string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
+		if _, err := s.GetMaster().Exec("DELETE FROM ChannelModerations WHERE ChannelId IN (SELECT Id FROM Channels WHERE TeamId = :TeamId)", map[string]interface{}{"TeamId": teamId}); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.PermanentDeleteByTeam", "store.sql_channel.permanent_delete_by_team.app_error", nil, "teamId="+teamId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.GetCacheProvider().Purge(model.CACHE_CHANNEL_MODERATIONS)
+
+		if _, err := s.GetMaster().Exec("DELETE FROM ChannelNameHistory WHERE TeamId = :TeamId", map[string]interface{}{"TeamId": teamId}); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.PermanentDeleteByTeam", "store.sql_channel.permanent_delete_by_team.app_error", nil, "teamId="+teamId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
 		if _, err := s.GetMaster().Exec("DELETE FROM Channels WHERE TeamId = :TeamId", map[string]interface{}{"TeamId": teamId}); err != nil {
 			result.Err = model.NewAppError("SqlChannelStore.PermanentDeleteByTeam", "store.sql_channel.permanent_delete_by_team.app_error", nil, "teamId="+teamId+", "+err.Error(), http.StatusInternalServerError)
 		}
@@ -403,15 +662,48 @@ func (s SqlChannelStore) PermanentDelete(channelId string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		if _, err := s.GetMaster().Exec("DELETE FROM Channels WHERE Id = :ChannelId", map[string]interface{}{"ChannelId": channelId}); err != nil {
 			result.Err = model.NewAppError("SqlChannelStore.PermanentDelete", "store.sql_channel.permanent_delete.app_error", nil, "channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := s.GetMaster().Exec("DELETE FROM ChannelModerations WHERE ChannelId = :ChannelId", map[string]interface{}{"ChannelId": channelId}); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.PermanentDelete", "store.sql_channel.permanent_delete.app_error", nil, "channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.GetCacheProvider().Invalidate(model.CACHE_CHANNEL_MODERATIONS, channelId)
+
+		if _, err := s.GetMaster().Exec("DELETE FROM ChannelNameHistory WHERE ChannelId = :ChannelId", map[string]interface{}{"ChannelId": channelId}); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.PermanentDelete", "store.sql_channel.permanent_delete.app_error", nil, "channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := s.GetMaster().Exec("DELETE FROM TeamDefaultChannels WHERE ChannelId = :ChannelId", map[string]interface{}{"ChannelId": channelId}); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.PermanentDelete", "store.sql_channel.permanent_delete.app_error", nil, "channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
 		}
 	})
 }
 
 func (s SqlChannelStore) PermanentDeleteMembersByChannel(channelId string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
-		_, err := s.GetMaster().Exec("DELETE FROM ChannelMembers WHERE ChannelId = :ChannelId", map[string]interface{}{"ChannelId": channelId})
+		transaction, err := s.GetMaster().Begin()
 		if err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.RemoveAllMembersByChannel", "store.sql_channel.remove_member.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := transaction.Exec("DELETE FROM ChannelMembers WHERE ChannelId = :ChannelId", map[string]interface{}{"ChannelId": channelId}); err != nil {
+			transaction.Rollback()
 			result.Err = model.NewAppError("SqlChannelStore.RemoveAllMembersByChannel", "store.sql_channel.remove_member.app_error", nil, "channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := transaction.Exec("UPDATE Channels SET MemberCount = 0, GuestCount = 0 WHERE Id = :ChannelId", map[string]interface{}{"ChannelId": channelId}); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlChannelStore.RemoveAllMembersByChannel", "store.sql_channel.update_member_counts.app_error", nil, "channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.RemoveAllMembersByChannel", "store.sql_channel.remove_member.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
 		}
 	})
 }
@@ -594,15 +886,9 @@ func (s SqlChannelStore) GetByNames(teamId string, names []string, allowFromCach
 					continue
 				}
 				visited[name] = struct{}{}
-				if cacheItem, ok := channelByNameCache.Get(teamId + name); ok {
-					if s.metrics != nil {
-						s.metrics.IncrementMemCacheHitCounter("Channel By Name")
-					}
+				if cacheItem, ok := s.channelByNameCache.Get(teamId + name); ok {
 					channels = append(channels, cacheItem.(*model.Channel))
 				} else {
-					if s.metrics != nil {
-						s.metrics.IncrementMemCacheMissCounter("Channel By Name")
-					}
 					misses = append(misses, name)
 				}
 			}
@@ -632,7 +918,7 @@ func (s SqlChannelStore) GetByNames(teamId string, names []string, allowFromCach
 				return
 			}
 			for _, channel := range dbChannels {
-				channelByNameCache.AddWithExpiresInSecs(teamId+channel.Name, channel, CHANNEL_CACHE_SEC)
+				s.channelByNameCache.AddWithDefaultExpires(teamId+channel.Name, channel)
 				channels = append(channels, channel)
 			}
 		}
@@ -656,16 +942,9 @@ func (s SqlChannelStore) getByName(teamId string, name string, includeDeleted bo
 		channel := model.Channel{}
 
 		if allowFromCache {
-			if cacheItem, ok := channelByNameCache.Get(teamId + name); ok {
-				if s.metrics != nil {
-					s.metrics.IncrementMemCacheHitCounter("Channel By Name")
-				}
+			if cacheItem, ok := s.channelByNameCache.Get(teamId + name); ok {
 				result.Data = cacheItem.(*model.Channel)
 				return
-			} else {
-				if s.metrics != nil {
-					s.metrics.IncrementMemCacheMissCounter("Channel By Name")
-				}
 			}
 		}
 		if err := s.GetReplica().SelectOne(&channel, query, map[string]interface{}{"TeamId": teamId, "Name": name}); err != nil {
@@ -676,7 +955,7 @@ func (s SqlChannelStore) getByName(teamId string, name string, includeDeleted bo
 			}
 		} else {
 			result.Data = &channel
-			channelByNameCache.AddWithExpiresInSecs(teamId+name, &channel, CHANNEL_CACHE_SEC)
+			s.channelByNameCache.AddWithDefaultExpires(teamId+name, &channel)
 		}
 	})
 }
@@ -701,7 +980,7 @@ func (s SqlChannelStore) GetDeleted(teamId string, offset int, limit int) store.
 	return store.Do(func(result *store.StoreResult) {
 		channels := &model.ChannelList{}
 
-		if _, err := s.GetReplica().Select(channels, "SELECT * FROM Channels WHERE (TeamId = :TeamId OR TeamId = '') AND DeleteAt != 0 ORDER BY DisplayName LIMIT :Limit OFFSET :Offset", map[string]interface{}{"TeamId": teamId, "Limit": limit, "Offset": offset}); err != nil {
+		if _, err := s.GetReplica().Select(channels, "SELECT * FROM Channels WHERE (TeamId = :TeamId OR TeamId = '') AND DeleteAt != 0 AND PermanentDeleteAt = 0 ORDER BY DisplayName LIMIT :Limit OFFSET :Offset", map[string]interface{}{"TeamId": teamId, "Limit": limit, "Offset": offset}); err != nil {
 			if err == sql.ErrNoRows {
 				result.Err = model.NewAppError("SqlChannelStore.GetDeleted", "store.sql_channel.get_deleted.missing.app_error", nil, "teamId="+teamId+", "+err.Error(), http.StatusNotFound)
 			} else {
@@ -753,6 +1032,8 @@ func (s SqlChannelStore) saveMemberT(transaction *gorp.Transaction, member *mode
 		} else {
 			result.Err = model.NewAppError("SqlChannelStore.SaveMember", "store.sql_channel.save_member.save.app_error", nil, "channel_id="+member.ChannelId+", user_id="+member.UserId+", "+err.Error(), http.StatusInternalServerError)
 		}
+	} else if err := updateMemberCountsT(transaction, member.UserId, member.ChannelId, 1); err != nil {
+		result.Err = model.NewAppError("SqlChannelStore.SaveMember", "store.sql_channel.update_member_counts.app_error", nil, "channel_id="+member.ChannelId+", user_id="+member.UserId+", "+err.Error(), http.StatusInternalServerError)
 	} else {
 		result.Data = member
 	}
@@ -760,6 +1041,34 @@ func (s SqlChannelStore) saveMemberT(transaction *gorp.Transaction, member *mode
 	return result
 }
 
+// updateMemberCountsT adds delta to the member and guest counts of a channel for the given user, or to those of
+// every channel that they're a member of if channelId is empty. Deactivated users aren't counted, so the counts
+// aren't changed for them.
+func updateMemberCountsT(transaction *gorp.Transaction, userId string, channelId string, delta int) error {
+	query := `
+		UPDATE
+			Channels
+		SET
+			MemberCount = MemberCount + :Delta * (SELECT COUNT(*) FROM Users WHERE Id = :UserId AND DeleteAt = 0),
+			GuestCount = GuestCount + :Delta * (SELECT COUNT(*) FROM Users WHERE Id = :UserId AND DeleteAt = 0 AND Roles LIKE :GuestRole)
+		WHERE
+			`
+
+	if channelId == "" {
+		query += "Id IN (SELECT ChannelId FROM ChannelMembers WHERE UserId = :UserId)"
+	} else {
+		query += "Id = :ChannelId"
+	}
+
+	_, err := transaction.Exec(query, map[string]interface{}{
+		"Delta":     delta,
+		"UserId":    userId,
+		"ChannelId": channelId,
+		"GuestRole": "%" + model.SYSTEM_GUEST_ROLE_ID + "%",
+	})
+	return err
+}
+
 func (s SqlChannelStore) UpdateMember(member *model.ChannelMember) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		member.PreUpdate()
@@ -776,6 +1085,53 @@ func (s SqlChannelStore) UpdateMember(member *model.ChannelMember) store.StoreCh
 	})
 }
 
+// UpdateMembers updates the members in one transaction, so that either all of them are updated or none are.
+func (s SqlChannelStore) UpdateMembers(members []*model.ChannelMember) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		for _, member := range members {
+			member.PreUpdate()
+
+			if result.Err = member.IsValid(); result.Err != nil {
+				return
+			}
+		}
+
+		transaction, err := s.GetMaster().Begin()
+		if err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.UpdateMembers", "store.sql_channel.update_members.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		for _, member := range members {
+			// The number of rows updated can't be used to find missing members since MySQL doesn't count rows that
+			// were already up to date
+			count, err := transaction.SelectInt("SELECT COUNT(*) FROM ChannelMembers WHERE ChannelId = :ChannelId AND UserId = :UserId", map[string]interface{}{"ChannelId": member.ChannelId, "UserId": member.UserId})
+			if err == nil && count == 0 {
+				transaction.Rollback()
+				result.Err = model.NewAppError("SqlChannelStore.UpdateMembers", store.MISSING_CHANNEL_MEMBER_ERROR, nil, "channel_id="+member.ChannelId+", "+"user_id="+member.UserId, http.StatusNotFound)
+				return
+			}
+
+			if err == nil {
+				_, err = transaction.Update(member)
+			}
+
+			if err != nil {
+				transaction.Rollback()
+				result.Err = model.NewAppError("SqlChannelStore.UpdateMembers", "store.sql_channel.update_member.app_error", nil, "channel_id="+member.ChannelId+", "+"user_id="+member.UserId+", "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.UpdateMembers", "store.sql_channel.update_members.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = members
+	})
+}
+
 func (s SqlChannelStore) GetMembers(channelId string, offset, limit int) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		var members model.ChannelMembers
@@ -963,22 +1319,12 @@ func (s SqlChannelStore) GetAllChannelMembersNotifyPropsForChannel(channelId str
 }
 
 func (s SqlChannelStore) InvalidateMemberCount(channelId string) {
-	channelMemberCountsCache.Remove(channelId)
-	if s.metrics != nil {
-		s.metrics.IncrementMemCacheInvalidationCounter("Channel Member Counts - Remove by ChannelId")
-	}
+	s.GetCacheProvider().InvalidateSkipClusterSend(model.CACHE_CHANNEL_MEMBER_COUNTS, channelId)
 }
 
 func (s SqlChannelStore) GetMemberCountFromCache(channelId string) int64 {
-	if cacheItem, ok := channelMemberCountsCache.Get(channelId); ok {
-		if s.metrics != nil {
-			s.metrics.IncrementMemCacheHitCounter("Channel Member Counts")
-		}
+	if cacheItem, ok := s.channelMemberCountsCache.Get(channelId); ok {
 		return cacheItem.(int64)
-	} else {
-		if s.metrics != nil {
-			s.metrics.IncrementMemCacheMissCounter("Channel Member Counts")
-		}
 	}
 
 	if result := <-s.GetMemberCount(channelId, true); result.Err != nil {
@@ -991,156 +1337,730 @@ func (s SqlChannelStore) GetMemberCountFromCache(channelId string) int64 {
 func (s SqlChannelStore) GetMemberCount(channelId string, allowFromCache bool) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		if allowFromCache {
-			if cacheItem, ok := channelMemberCountsCache.Get(channelId); ok {
-				if s.metrics != nil {
-					s.metrics.IncrementMemCacheHitCounter("Channel Member Counts")
-				}
+			if cacheItem, ok := s.channelMemberCountsCache.Get(channelId); ok {
 				result.Data = cacheItem.(int64)
 				return
-			} else {
-				if s.metrics != nil {
-					s.metrics.IncrementMemCacheMissCounter("Channel Member Counts")
-				}
-			}
-		} else {
-			if s.metrics != nil {
-				s.metrics.IncrementMemCacheMissCounter("Channel Member Counts")
 			}
+		} else if s.metrics != nil {
+			s.metrics.IncrementMemCacheMissCounter(s.channelMemberCountsCache.Name())
 		}
 
-		count, err := s.GetReplica().SelectInt(`
-			SELECT
-				count(*)
-			FROM
-				ChannelMembers,
-				Users
-			WHERE
-				ChannelMembers.UserId = Users.Id
-				AND ChannelMembers.ChannelId = :ChannelId
-				AND Users.DeleteAt = 0`, map[string]interface{}{"ChannelId": channelId})
+		count, err := s.GetReplica().SelectInt("SELECT MemberCount FROM Channels WHERE Id = :ChannelId", map[string]interface{}{"ChannelId": channelId})
 		if err != nil {
 			result.Err = model.NewAppError("SqlChannelStore.GetMemberCount", "store.sql_channel.get_member_count.app_error", nil, "channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
 		} else {
 			result.Data = count
 
 			if allowFromCache {
-				channelMemberCountsCache.AddWithExpiresInSecs(channelId, count, CHANNEL_MEMBERS_COUNTS_CACHE_SEC)
+				s.channelMemberCountsCache.AddWithDefaultExpires(channelId, count)
+			}
+		}
+	})
+}
+
+// GetGuestCount returns the number of active guests in a channel.
+func (s SqlChannelStore) GetGuestCount(channelId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		count, err := s.GetReplica().SelectInt("SELECT GuestCount FROM Channels WHERE Id = :ChannelId", map[string]interface{}{"ChannelId": channelId})
+		if err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.GetGuestCount", "store.sql_channel.get_guest_count.app_error", nil, "channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = count
+		}
+	})
+}
+
+// countedMembersQuery counts the members of the channel in the enclosing query that are included in its member
+// count. GUEST_CLAUSE is replaced to optionally count only guests.
+const countedMembersQuery = `
+	SELECT
+		COUNT(*)
+	FROM
+		ChannelMembers,
+		Users
+	WHERE
+		ChannelMembers.ChannelId = Channels.Id
+		AND ChannelMembers.UserId = Users.Id
+		AND Users.DeleteAt = 0
+		GUEST_CLAUSE`
+
+// ReconcileMemberCounts recounts the members and guests of up to limit channels, ordered by id, after the one with
+// the given id, and fixes the counts of any where they've drifted.
+func (s SqlChannelStore) ReconcileMemberCounts(afterChannelId string, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var channelIds []string
+		if _, err := s.GetMaster().Select(&channelIds, "SELECT Id FROM Channels WHERE Id > :AfterChannelId ORDER BY Id LIMIT :Limit", map[string]interface{}{"AfterChannelId": afterChannelId, "Limit": limit}); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.ReconcileMemberCounts", "store.sql_channel.reconcile_member_counts.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if len(channelIds) == 0 {
+			result.Data = &model.ChannelMemberCountsBatch{}
+			return
+		}
+
+		props := map[string]interface{}{"GuestRole": "%" + model.SYSTEM_GUEST_ROLE_ID + "%"}
+		idQuery := ""
+
+		for index, channelId := range channelIds {
+			if len(idQuery) > 0 {
+				idQuery += ", "
 			}
+
+			props["channelId"+strconv.Itoa(index)] = channelId
+			idQuery += ":channelId" + strconv.Itoa(index)
+		}
+
+		memberCount := "(" + strings.Replace(countedMembersQuery, "GUEST_CLAUSE", "", 1) + ")"
+		guestCount := "(" + strings.Replace(countedMembersQuery, "GUEST_CLAUSE", "AND Users.Roles LIKE :GuestRole", 1) + ")"
+
+		query := `
+			UPDATE
+				Channels
+			SET
+				MemberCount = ` + memberCount + `,
+				GuestCount = ` + guestCount + `
+			WHERE
+				Id IN (` + idQuery + `)
+				AND (MemberCount != ` + memberCount + ` OR GuestCount != ` + guestCount + `)`
+
+		sqlResult, err := s.GetMaster().Exec(query, props)
+		if err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.ReconcileMemberCounts", "store.sql_channel.reconcile_member_counts.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		corrected, err := sqlResult.RowsAffected()
+		if err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.ReconcileMemberCounts", "store.sql_channel.reconcile_member_counts.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = &model.ChannelMemberCountsBatch{
+			LastChannelId: channelIds[len(channelIds)-1],
+			Corrected:     corrected,
+		}
+	})
+}
+
+// GetInactivePublicChannels returns the activity of up to limit public channels, ordered by id, after the one with the
+// given id, that were created before inactiveSince and haven't had a post since then that wasn't a system message.
+func (s SqlChannelStore) GetInactivePublicChannels(inactiveSince int64, afterChannelId string, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		query := `
+			SELECT
+				Channels.Id AS ChannelId,
+				COALESCE((
+					SELECT
+						MAX(Posts.CreateAt)
+					FROM
+						Posts
+					WHERE
+						Posts.ChannelId = Channels.Id
+						AND Posts.DeleteAt = 0
+						AND Posts.Type NOT LIKE :SystemPostTypes
+				), Channels.CreateAt) AS LastActivityAt,
+				COALESCE((
+					SELECT
+						MAX(Posts.CreateAt)
+					FROM
+						Posts
+					WHERE
+						Posts.ChannelId = Channels.Id
+						AND Posts.DeleteAt = 0
+						AND Posts.Type = :WarningPostType
+				), 0) AS WarnedAt
+			FROM
+				Channels
+			WHERE
+				Channels.Id > :AfterChannelId
+				AND Channels.Type = :ChannelType
+				AND Channels.DeleteAt = 0
+				AND Channels.CreateAt < :InactiveSince
+				AND NOT EXISTS (
+					SELECT
+						1
+					FROM
+						Posts
+					WHERE
+						Posts.ChannelId = Channels.Id
+						AND Posts.CreateAt >= :InactiveSince
+						AND Posts.DeleteAt = 0
+						AND Posts.Type NOT LIKE :SystemPostTypes
+				)
+			ORDER BY
+				Channels.Id
+			LIMIT :Limit`
+
+		var activity []*model.ChannelActivity
+		if _, err := s.GetReplica().Select(&activity, query, map[string]interface{}{
+			"AfterChannelId":  afterChannelId,
+			"ChannelType":     model.CHANNEL_OPEN,
+			"InactiveSince":   inactiveSince,
+			"SystemPostTypes": model.POST_SYSTEM_MESSAGE_PREFIX + "%",
+			"WarningPostType": model.POST_CHANNEL_INACTIVE,
+			"Limit":           limit,
+		}); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.GetInactivePublicChannels", "store.sql_channel.get_inactive_public_channels.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
 		}
+
+		result.Data = activity
 	})
 }
 
 func (s SqlChannelStore) RemoveMember(channelId string, userId string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
-		_, err := s.GetMaster().Exec("DELETE FROM ChannelMembers WHERE ChannelId = :ChannelId AND UserId = :UserId", map[string]interface{}{"ChannelId": channelId, "UserId": userId})
+		transaction, err := s.GetMaster().Begin()
 		if err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.RemoveMember", "store.sql_channel.remove_member.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		sqlResult, err := transaction.Exec("DELETE FROM ChannelMembers WHERE ChannelId = :ChannelId AND UserId = :UserId", map[string]interface{}{"ChannelId": channelId, "UserId": userId})
+		if err != nil {
+			transaction.Rollback()
 			result.Err = model.NewAppError("SqlChannelStore.RemoveMember", "store.sql_channel.remove_member.app_error", nil, "channel_id="+channelId+", user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Only update the counts if the user was actually a member since removing a member is idempotent
+		if rows, _ := sqlResult.RowsAffected(); rows > 0 {
+			if err := updateMemberCountsT(transaction, userId, channelId, -1); err != nil {
+				transaction.Rollback()
+				result.Err = model.NewAppError("SqlChannelStore.RemoveMember", "store.sql_channel.update_member_counts.app_error", nil, "channel_id="+channelId+", user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.RemoveMember", "store.sql_channel.remove_member.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
 		}
 	})
 }
 
 func (s SqlChannelStore) PermanentDeleteMembersByUser(userId string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
-		if _, err := s.GetMaster().Exec("DELETE FROM ChannelMembers WHERE UserId = :UserId", map[string]interface{}{"UserId": userId}); err != nil {
+		transaction, err := s.GetMaster().Begin()
+		if err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.RemoveMember", "store.sql_channel.remove_member.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := updateMemberCountsT(transaction, userId, "", -1); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlChannelStore.RemoveMember", "store.sql_channel.update_member_counts.app_error", nil, "user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := transaction.Exec("DELETE FROM ChannelMembers WHERE UserId = :UserId", map[string]interface{}{"UserId": userId}); err != nil {
+			transaction.Rollback()
 			result.Err = model.NewAppError("SqlChannelStore.RemoveMember", "store.sql_channel.permanent_delete_members_by_user.app_error", nil, "user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.RemoveMember", "store.sql_channel.remove_member.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
 		}
 	})
 }
 
 func (s SqlChannelStore) UpdateLastViewedAt(channelIds []string, userId string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
-		props := make(map[string]interface{})
+		views, err := s.getViewedAt(channelIds)
+		if err == nil {
+			err = s.updateViewedAt(userId, views)
+		}
 
-		updateIdQuery := ""
-		for index, channelId := range channelIds {
-			if len(updateIdQuery) > 0 {
-				updateIdQuery += " OR "
-			}
+		if err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.UpdateLastViewedAt", "store.sql_channel.update_last_viewed_at.app_error", nil, "channel_ids="+strings.Join(channelIds, ",")+", user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
 
-			props["channelId"+strconv.Itoa(index)] = channelId
-			updateIdQuery += "ChannelId = :channelId" + strconv.Itoa(index)
+		times := map[string]int64{}
+		for _, view := range views {
+			times[view.ChannelId] = view.LastPostAt
 		}
 
-		selectIdQuery := strings.Replace(updateIdQuery, "ChannelId", "Id", -1)
+		result.Data = times
+	})
+}
+
+// GetViewedAt returns what the read positions of the channels' members would be set to if they viewed them now.
+func (s SqlChannelStore) GetViewedAt(channelIds []string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if views, err := s.getViewedAt(channelIds); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.GetViewedAt", "store.sql_channel.get_viewed_at.app_error", nil, "channel_ids="+strings.Join(channelIds, ",")+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = views
+		}
+	})
+}
 
-		var lastPostAtTimes []struct {
-			Id            string
-			LastPostAt    int64
-			TotalMsgCount int64
+// UpdateViewedAt moves the user's read positions in the channels forward to the given ones, clearing their mentions.
+func (s SqlChannelStore) UpdateViewedAt(userId string, views []*model.ChannelViewedAt) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if err := s.updateViewedAt(userId, views); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.UpdateViewedAt", "store.sql_channel.update_viewed_at.app_error", nil, "user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
 		}
+	})
+}
 
-		selectQuery := "SELECT Id, LastPostAt, TotalMsgCount FROM Channels WHERE (" + selectIdQuery + ")"
+func (s SqlChannelStore) getViewedAt(channelIds []string) ([]*model.ChannelViewedAt, error) {
+	props := make(map[string]interface{})
 
-		if _, err := s.GetMaster().Select(&lastPostAtTimes, selectQuery, props); err != nil {
-			result.Err = model.NewAppError("SqlChannelStore.UpdateLastViewedAt", "store.sql_channel.update_last_viewed_at.app_error", nil, "channel_ids="+strings.Join(channelIds, ",")+", user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
-			return
+	idQuery := ""
+	for index, channelId := range channelIds {
+		if len(idQuery) > 0 {
+			idQuery += " OR "
 		}
 
-		times := map[string]int64{}
-		msgCountQuery := ""
-		lastViewedQuery := ""
-		for index, t := range lastPostAtTimes {
-			times[t.Id] = t.LastPostAt
+		props["channelId"+strconv.Itoa(index)] = channelId
+		idQuery += "Id = :channelId" + strconv.Itoa(index)
+	}
+
+	var views []*model.ChannelViewedAt
+	if _, err := s.GetMaster().Select(&views, "SELECT Id ChannelId, LastPostAt, TotalMsgCount FROM Channels WHERE ("+idQuery+")", props); err != nil {
+		return nil, err
+	}
 
-			props["msgCount"+strconv.Itoa(index)] = t.TotalMsgCount
-			msgCountQuery += fmt.Sprintf("WHEN :channelId%d THEN GREATEST(MsgCount, :msgCount%d) ", index, index)
+	return views, nil
+}
+
+func (s SqlChannelStore) updateViewedAt(userId string, views []*model.ChannelViewedAt) error {
+	if len(views) == 0 {
+		return nil
+	}
 
-			props["lastViewed"+strconv.Itoa(index)] = t.LastPostAt
-			lastViewedQuery += fmt.Sprintf("WHEN :channelId%d THEN GREATEST(LastViewedAt, :lastViewed%d) ", index, index)
+	props := make(map[string]interface{})
 
-			props["channelId"+strconv.Itoa(index)] = t.Id
+	updateIdQuery := ""
+	msgCountQuery := ""
+	lastViewedQuery := ""
+	for index, view := range views {
+		if len(updateIdQuery) > 0 {
+			updateIdQuery += " OR "
 		}
 
-		var updateQuery string
+		props["channelId"+strconv.Itoa(index)] = view.ChannelId
+		updateIdQuery += "ChannelId = :channelId" + strconv.Itoa(index)
 
-		if s.DriverName() == model.DATABASE_DRIVER_POSTGRES {
-			updateQuery = `UPDATE
+		props["msgCount"+strconv.Itoa(index)] = view.TotalMsgCount
+		msgCountQuery += fmt.Sprintf("WHEN :channelId%d THEN GREATEST(MsgCount, :msgCount%d) ", index, index)
+
+		props["lastViewed"+strconv.Itoa(index)] = view.LastPostAt
+		lastViewedQuery += fmt.Sprintf("WHEN :channelId%d THEN GREATEST(LastViewedAt, :lastViewed%d) ", index, index)
+	}
+
+	var updateQuery string
+
+	if s.DriverName() == model.DATABASE_DRIVER_POSTGRES {
+		updateQuery = `UPDATE
+			ChannelMembers
+		SET
+		    MentionCount = 0,
+		    MsgCount = CAST(CASE ChannelId ` + msgCountQuery + ` END AS BIGINT),
+		    LastViewedAt = CAST(CASE ChannelId ` + lastViewedQuery + ` END AS BIGINT),
+		    LastUpdateAt = CAST(CASE ChannelId ` + lastViewedQuery + ` END AS BIGINT)
+		WHERE
+		        UserId = :UserId
+		        AND (` + updateIdQuery + `)`
+	} else if s.DriverName() == model.DATABASE_DRIVER_MYSQL {
+		updateQuery = `UPDATE
+			ChannelMembers
+		SET
+		    MentionCount = 0,
+		    MsgCount = CASE ChannelId ` + msgCountQuery + ` END,
+		    LastViewedAt = CASE ChannelId ` + lastViewedQuery + ` END,
+		    LastUpdateAt = CASE ChannelId ` + lastViewedQuery + ` END
+		WHERE
+		        UserId = :UserId
+		        AND (` + updateIdQuery + `)`
+	}
+
+	props["UserId"] = userId
+
+	_, err := s.GetMaster().Exec(updateQuery, props)
+	return err
+}
+
+func (s SqlChannelStore) IncrementMentionCount(channelId string, userId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		_, err := s.GetMaster().Exec(
+			`UPDATE
 				ChannelMembers
 			SET
-			    MentionCount = 0,
-			    MsgCount = CAST(CASE ChannelId ` + msgCountQuery + ` END AS BIGINT),
-			    LastViewedAt = CAST(CASE ChannelId ` + lastViewedQuery + ` END AS BIGINT),
-			    LastUpdateAt = CAST(CASE ChannelId ` + lastViewedQuery + ` END AS BIGINT)
+				MentionCount = MentionCount + 1,
+				LastUpdateAt = :LastUpdateAt
 			WHERE
-			        UserId = :UserId
-			        AND (` + updateIdQuery + `)`
-		} else if s.DriverName() == model.DATABASE_DRIVER_MYSQL {
-			updateQuery = `UPDATE
+				UserId = :UserId
+					AND ChannelId = :ChannelId`,
+			map[string]interface{}{"ChannelId": channelId, "UserId": userId, "LastUpdateAt": model.GetMillis()})
+		if err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.IncrementMentionCount", "store.sql_channel.increment_mention_count.app_error", nil, "channel_id="+channelId+", user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// UpdateLastViewedAtPost marks the channel as read by the user up to just before the given post, counting the given
+// post and any posts made after it as unread messages, and sets their unread mentions to mentionCount.
+// uncountedPostTypes are the types of posts that aren't counted in a channel's TotalMsgCount, so that they don't leave
+// the channel unread.
+var uncountedPostTypes = []string{
+	model.POST_JOIN_LEAVE, model.POST_ADD_REMOVE,
+	model.POST_JOIN_CHANNEL, model.POST_LEAVE_CHANNEL,
+	model.POST_JOIN_TEAM, model.POST_LEAVE_TEAM,
+	model.POST_ADD_TO_CHANNEL, model.POST_REMOVE_FROM_CHANNEL,
+}
+
+func isCountedPost(post *model.Post) bool {
+	if post.OriginalId != "" {
+		return false
+	}
+
+	for _, postType := range uncountedPostTypes {
+		if post.Type == postType {
+			return false
+		}
+	}
+
+	return true
+}
+
+// UpdateLastViewedAtPost marks the channel as only having been read up to just before the post, moving the user's
+// last read post back to the one before it.
+func (s SqlChannelStore) UpdateLastViewedAtPost(unreadPost *model.Post, userId string, mentionCount int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		props := map[string]interface{}{
+			"ChannelId":    unreadPost.ChannelId,
+			"UserId":       userId,
+			"CreateAt":     unreadPost.CreateAt,
+			"LastViewedAt": unreadPost.CreateAt - 1,
+			"LastUpdateAt": model.GetMillis(),
+			"MentionCount": mentionCount,
+		}
+
+		// only count the kinds of posts that are counted in the channel's TotalMsgCount, leaving out the versions
+		// of posts from before they were edited
+		typeQuery := ""
+		for index, postType := range uncountedPostTypes {
+			if len(typeQuery) > 0 {
+				typeQuery += ", "
+			}
+
+			props["Type"+strconv.Itoa(index)] = postType
+			typeQuery += ":Type" + strconv.Itoa(index)
+		}
+
+		if _, err := s.GetMaster().Exec(
+			`UPDATE
 				ChannelMembers
 			SET
-			    MentionCount = 0,
-			    MsgCount = CASE ChannelId ` + msgCountQuery + ` END,
-			    LastViewedAt = CASE ChannelId ` + lastViewedQuery + ` END,
-			    LastUpdateAt = CASE ChannelId ` + lastViewedQuery + ` END
+				MentionCount = :MentionCount,
+				MsgCount = GREATEST((SELECT TotalMsgCount FROM Channels WHERE Id = :ChannelId) - (
+					SELECT
+						COUNT(*)
+					FROM
+						Posts
+					WHERE
+						ChannelId = :ChannelId
+						AND CreateAt >= :CreateAt
+						AND DeleteAt = 0
+						AND OriginalId = ''
+						AND Type NOT IN (`+typeQuery+`)
+				), 0),
+				LastViewedAt = :LastViewedAt,
+				LastReadPostId = COALESCE((
+					SELECT
+						Id
+					FROM
+						Posts
+					WHERE
+						ChannelId = :ChannelId
+						AND CreateAt < :CreateAt
+						AND DeleteAt = 0
+					ORDER BY CreateAt DESC
+					LIMIT 1
+				), ''),
+				LastReadPostAt = :LastViewedAt,
+				LastUpdateAt = :LastUpdateAt
+			WHERE
+				UserId = :UserId
+				AND ChannelId = :ChannelId`, props); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.UpdateLastViewedAtPost", "store.sql_channel.update_last_viewed_at_post.app_error", nil, "channel_id="+unreadPost.ChannelId+", user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var unreadChannel model.ChannelUnread
+		if err := s.GetMaster().SelectOne(&unreadChannel,
+			`SELECT
+				Channels.TeamId TeamId, Channels.Id ChannelId, (Channels.TotalMsgCount - ChannelMembers.MsgCount) MsgCount, ChannelMembers.MentionCount MentionCount, ChannelMembers.LastViewedAt LastViewedAt, ChannelMembers.LastReadPostId LastReadPostId, ChannelMembers.NotifyProps NotifyProps
+			FROM
+				Channels, ChannelMembers
 			WHERE
-			        UserId = :UserId
-			        AND (` + updateIdQuery + `)`
+				Id = ChannelId
+				AND Id = :ChannelId
+				AND UserId = :UserId`, props); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.UpdateLastViewedAtPost", "store.sql_channel.update_last_viewed_at_post.app_error", nil, "channel_id="+unreadPost.ChannelId+", user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+			if err == sql.ErrNoRows {
+				result.Err.StatusCode = http.StatusNotFound
+			}
+		} else {
+			result.Data = &unreadChannel
 		}
+	})
+}
 
-		props["UserId"] = userId
+// UpdateLastReadPost moves the user's last read post in the post's channel forward to the post, leaving it as it is
+// if they've already read a later one, and returns the channel's unread state afterwards.
+func (s SqlChannelStore) UpdateLastReadPost(post *model.Post, userId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		props := map[string]interface{}{
+			"ChannelId":    post.ChannelId,
+			"UserId":       userId,
+			"PostId":       post.Id,
+			"CreateAt":     post.CreateAt,
+			"LastUpdateAt": model.GetMillis(),
+		}
 
-		if _, err := s.GetMaster().Exec(updateQuery, props); err != nil {
-			result.Err = model.NewAppError("SqlChannelStore.UpdateLastViewedAt", "store.sql_channel.update_last_viewed_at.app_error", nil, "channel_ids="+strings.Join(channelIds, ",")+", user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+		if _, err := s.GetMaster().Exec(
+			`UPDATE
+				ChannelMembers
+			SET
+				LastReadPostId = :PostId,
+				LastReadPostAt = :CreateAt,
+				LastUpdateAt = :LastUpdateAt
+			WHERE
+				UserId = :UserId
+				AND ChannelId = :ChannelId
+				AND LastReadPostAt < :CreateAt`, props); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.UpdateLastReadPost", "store.sql_channel.update_last_read_post.app_error", nil, "channel_id="+post.ChannelId+", user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var unreadChannel model.ChannelUnread
+		if err := s.GetMaster().SelectOne(&unreadChannel,
+			`SELECT
+				Channels.TeamId TeamId, Channels.Id ChannelId, (Channels.TotalMsgCount - ChannelMembers.MsgCount) MsgCount, ChannelMembers.MentionCount MentionCount, ChannelMembers.LastViewedAt LastViewedAt, ChannelMembers.LastReadPostId LastReadPostId, ChannelMembers.NotifyProps NotifyProps
+			FROM
+				Channels, ChannelMembers
+			WHERE
+				Id = ChannelId
+				AND Id = :ChannelId
+				AND UserId = :UserId`, props); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.UpdateLastReadPost", "store.sql_channel.update_last_read_post.app_error", nil, "channel_id="+post.ChannelId+", user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+			if err == sql.ErrNoRows {
+				result.Err.StatusCode = http.StatusNotFound
+			}
 		} else {
-			result.Data = times
+			result.Data = &unreadChannel
 		}
 	})
 }
 
-func (s SqlChannelStore) IncrementMentionCount(channelId string, userId string) store.StoreChannel {
+// UpdateLastReadPostsToLatest moves the user's last read posts in the channels to the latest post in each of them,
+// returning a map of the channels' ids to their last read posts.
+func (s SqlChannelStore) UpdateLastReadPostsToLatest(channelIds []string, userId string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
-		_, err := s.GetMaster().Exec(
+		props := map[string]interface{}{
+			"UserId":       userId,
+			"LastUpdateAt": model.GetMillis(),
+		}
+
+		idQuery := ""
+		for index, channelId := range channelIds {
+			if len(idQuery) > 0 {
+				idQuery += ", "
+			}
+
+			props["channelId"+strconv.Itoa(index)] = channelId
+			idQuery += ":channelId" + strconv.Itoa(index)
+		}
+
+		if _, err := s.GetMaster().Exec(
 			`UPDATE
 				ChannelMembers
 			SET
-				MentionCount = MentionCount + 1,
+				LastReadPostId = COALESCE((
+					SELECT
+						Id
+					FROM
+						Posts
+					WHERE
+						Posts.ChannelId = ChannelMembers.ChannelId
+						AND Posts.DeleteAt = 0
+					ORDER BY Posts.CreateAt DESC
+					LIMIT 1
+				), ''),
+				LastReadPostAt = COALESCE((
+					SELECT
+						MAX(Posts.CreateAt)
+					FROM
+						Posts
+					WHERE
+						Posts.ChannelId = ChannelMembers.ChannelId
+						AND Posts.DeleteAt = 0
+				), 0),
 				LastUpdateAt = :LastUpdateAt
 			WHERE
 				UserId = :UserId
-					AND ChannelId = :ChannelId`,
-			map[string]interface{}{"ChannelId": channelId, "UserId": userId, "LastUpdateAt": model.GetMillis()})
+				AND ChannelId IN (`+idQuery+`)`, props); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.UpdateLastReadPostsToLatest", "store.sql_channel.update_last_read_posts_to_latest.app_error", nil, "channel_ids="+strings.Join(channelIds, ",")+", user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var members []*model.ChannelMember
+		if _, err := s.GetMaster().Select(&members, "SELECT ChannelId, LastReadPostId FROM ChannelMembers WHERE UserId = :UserId AND ChannelId IN ("+idQuery+")", props); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.UpdateLastReadPostsToLatest", "store.sql_channel.update_last_read_posts_to_latest.app_error", nil, "channel_ids="+strings.Join(channelIds, ",")+", user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		lastReadPostIds := map[string]string{}
+		for _, member := range members {
+			lastReadPostIds[member.ChannelId] = member.LastReadPostId
+		}
+
+		result.Data = lastReadPostIds
+	})
+}
+
+// GetMembersWithUnreadMentions returns the members of the channel that have unread mentions and last viewed it before
+// the given time, since those are the only members whose mention counts could include posts from after then.
+func (s SqlChannelStore) GetMembersWithUnreadMentions(channelId string, viewedBefore int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var members model.ChannelMembers
+		if _, err := s.GetMaster().Select(&members,
+			`SELECT
+				*
+			FROM
+				ChannelMembers
+			WHERE
+				ChannelId = :ChannelId
+				AND MentionCount > 0
+				AND LastViewedAt < :ViewedBefore`, map[string]interface{}{"ChannelId": channelId, "ViewedBefore": viewedBefore}); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.GetMembersWithUnreadMentions", "store.sql_channel.get_members_with_unread_mentions.app_error", nil, "channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = &members
+		}
+	})
+}
+
+// RemovePostsFromCounts takes posts that have been deleted from the channel out of its TotalMsgCount and out of the
+// MsgCount of every member that had already read them, so that the channel's unread messages stay the same for the
+// members that had read them and go down for everyone else. The given number of mentions is also taken away from each
+// user's MentionCount.
+func (s SqlChannelStore) RemovePostsFromCounts(channelId string, posts []*model.Post, mentionCounts map[string]int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		props := map[string]interface{}{
+			"ChannelId":    channelId,
+			"LastUpdateAt": model.GetMillis(),
+		}
+
+		readQuery := ""
+		var counted int64
+		var oldest int64
+		for _, post := range posts {
+			if !isCountedPost(post) {
+				continue
+			}
+
+			if len(readQuery) > 0 {
+				readQuery += " + "
+			}
+
+			key := "CreateAt" + strconv.FormatInt(counted, 10)
+			props[key] = post.CreateAt
+			readQuery += "CASE WHEN LastViewedAt >= :" + key + " THEN 1 ELSE 0 END"
+
+			if counted == 0 || post.CreateAt < oldest {
+				oldest = post.CreateAt
+			}
+			counted++
+		}
+
+		if counted == 0 && len(mentionCounts) == 0 {
+			return
+		}
+
+		transaction, err := s.GetMaster().Begin()
 		if err != nil {
-			result.Err = model.NewAppError("SqlChannelStore.IncrementMentionCount", "store.sql_channel.increment_mention_count.app_error", nil, "channel_id="+channelId+", user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+			result.Err = model.NewAppError("SqlChannelStore.RemovePostsFromCounts", "store.sql_channel.remove_posts_from_counts.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if counted > 0 {
+			props["Count"] = counted
+			props["Oldest"] = oldest
+
+			// Members that last viewed the channel before the oldest post hadn't read any of them
+			if _, err := transaction.Exec(
+				`UPDATE
+					ChannelMembers
+				SET
+					MsgCount = GREATEST(MsgCount - (`+readQuery+`), 0),
+					LastUpdateAt = :LastUpdateAt
+				WHERE
+					ChannelId = :ChannelId
+					AND LastViewedAt >= :Oldest`, props); err != nil {
+				transaction.Rollback()
+				result.Err = model.NewAppError("SqlChannelStore.RemovePostsFromCounts", "store.sql_channel.remove_posts_from_counts.app_error", nil, "channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			if _, err := transaction.Exec("UPDATE Channels SET TotalMsgCount = GREATEST(TotalMsgCount - :Count, 0) WHERE Id = :ChannelId", props); err != nil {
+				transaction.Rollback()
+				result.Err = model.NewAppError("SqlChannelStore.RemovePostsFromCounts", "store.sql_channel.remove_posts_from_counts.app_error", nil, "channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		for userId, mentions := range mentionCounts {
+			if _, err := transaction.Exec(
+				`UPDATE
+					ChannelMembers
+				SET
+					MentionCount = GREATEST(MentionCount - :Mentions, 0),
+					LastUpdateAt = :LastUpdateAt
+				WHERE
+					ChannelId = :ChannelId
+					AND UserId = :UserId`, map[string]interface{}{"ChannelId": channelId, "UserId": userId, "Mentions": mentions, "LastUpdateAt": props["LastUpdateAt"]}); err != nil {
+				transaction.Rollback()
+				result.Err = model.NewAppError("SqlChannelStore.RemovePostsFromCounts", "store.sql_channel.remove_posts_from_counts.app_error", nil, "channel_id="+channelId+", user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.RemovePostsFromCounts", "store.sql_channel.remove_posts_from_counts.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// IncrementMsgCountForUsersWithPreference marks the latest post in the channel as read for every member with the given
+// preference, so that posts they've chosen to hide don't leave the channel unread for them.
+func (s SqlChannelStore) IncrementMsgCountForUsersWithPreference(channelId string, category string, name string, value string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		_, err := s.GetMaster().Exec(
+			`UPDATE
+				ChannelMembers
+			SET
+				MsgCount = MsgCount + 1,
+				LastUpdateAt = :LastUpdateAt
+			WHERE
+				ChannelId = :ChannelId
+					AND UserId IN (
+						SELECT
+							UserId
+						FROM
+							Preferences
+						WHERE
+							Category = :Category
+								AND Name = :Name
+								AND Value = :Value
+					)`,
+			map[string]interface{}{"ChannelId": channelId, "Category": category, "Name": name, "Value": value, "LastUpdateAt": model.GetMillis()})
+		if err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.IncrementMsgCountForUsersWithPreference", "store.sql_channel.increment_msg_count_for_users_with_preference.app_error", nil, "channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
 		}
 	})
 }
@@ -1148,7 +2068,7 @@ func (s SqlChannelStore) IncrementMentionCount(channelId string, userId string)
 func (s SqlChannelStore) GetAll(teamId string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		var data []*model.Channel
-		_, err := s.GetReplica().Select(&data, "SELECT * FROM Channels WHERE TeamId = :TeamId AND Type != 'D' ORDER BY Name", map[string]interface{}{"TeamId": teamId})
+		_, err := s.GetReplica().Select(&data, "SELECT * FROM Channels WHERE TeamId = :TeamId AND Type != 'D' AND PermanentDeleteAt = 0 ORDER BY Name", map[string]interface{}{"TeamId": teamId})
 
 		if err != nil {
 			result.Err = model.NewAppError("SqlChannelStore.GetAll", "store.sql_channel.get_all.app_error", nil, "teamId="+teamId+", err="+err.Error(), http.StatusInternalServerError)
@@ -1158,6 +2078,19 @@ func (s SqlChannelStore) GetAll(teamId string) store.StoreChannel {
 	})
 }
 
+// GetByIconEmojiName returns the channels, including deleted ones, that use the emoji with the given name as their icon.
+func (s SqlChannelStore) GetByIconEmojiName(emojiName string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var channels []*model.Channel
+		if _, err := s.GetMaster().Select(&channels, "SELECT * FROM Channels WHERE IconEmojiName = :EmojiName", map[string]interface{}{"EmojiName": emojiName}); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.GetByIconEmojiName", "store.sql_channel.get_by_icon_emoji_name.app_error", nil, "emoji_name="+emojiName+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = channels
+	})
+}
+
 func (s SqlChannelStore) GetForPost(postId string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		channel := &model.Channel{}
@@ -1212,6 +2145,19 @@ func (s SqlChannelStore) AnalyticsDeletedTypeCount(teamId string, channelType st
 	})
 }
 
+// AnalyticsFeedCount returns the number of channels that haven't been deleted with their feed turned on.
+func (s SqlChannelStore) AnalyticsFeedCount() store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		count, err := s.GetReplica().SelectInt("SELECT COUNT(Id) FROM Channels WHERE EnableFeed = :EnableFeed AND DeleteAt = 0", map[string]interface{}{"EnableFeed": true})
+		if err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.AnalyticsFeedCount", "store.sql_channel.analytics_feed_count.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = count
+	})
+}
+
 func (s SqlChannelStore) GetMembersForUser(teamId string, userId string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		members := &model.ChannelMembers{}
@@ -1245,6 +2191,7 @@ func (s SqlChannelStore) AutocompleteInTeam(teamId string, term string) store.St
 				AND Type = 'O'
 				AND DeleteAt = 0
 				%v
+			ORDER BY MemberCount DESC
 			LIMIT 50`
 
 		var channels model.ChannelList
@@ -1259,14 +2206,18 @@ func (s SqlChannelStore) AutocompleteInTeam(teamId string, term string) store.St
 			fulltextClause, fulltextTerm := s.buildFulltextClause(term)
 			likeQuery := fmt.Sprintf(queryFormat, "AND "+likeClause)
 			fulltextQuery := fmt.Sprintf(queryFormat, "AND "+fulltextClause)
-			query := fmt.Sprintf("(%v) UNION (%v) LIMIT 50", likeQuery, fulltextQuery)
+			query := fmt.Sprintf("(%v) UNION (%v) ORDER BY MemberCount DESC LIMIT 50", likeQuery, fulltextQuery)
 
 			if _, err := s.GetReplica().Select(&channels, query, map[string]interface{}{"TeamId": teamId, "LikeTerm": likeTerm, "FulltextTerm": fulltextTerm}); err != nil {
 				result.Err = model.NewAppError("SqlChannelStore.AutocompleteInTeam", "store.sql_channel.search.app_error", nil, "term="+term+", "+", "+err.Error(), http.StatusInternalServerError)
 			}
 		}
 
+		// Rank the largest channels first since they're the ones most likely to be looked for
 		sort.Slice(channels, func(a, b int) bool {
+			if channels[a].MemberCount != channels[b].MemberCount {
+				return channels[a].MemberCount > channels[b].MemberCount
+			}
 			return strings.ToLower(channels[a].DisplayName) < strings.ToLower(channels[b].DisplayName)
 		})
 		result.Data = &channels
@@ -1321,6 +2272,28 @@ func (s SqlChannelStore) SearchMore(userId string, teamId string, term string) s
 	})
 }
 
+func (s SqlChannelStore) SearchForUserInTeam(userId string, teamId string, term string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		searchQuery := `
+			SELECT
+			    Channels.*
+			FROM
+			    Channels,
+			    ChannelMembers
+			WHERE
+			    Channels.Id = ChannelMembers.ChannelId
+			    AND ChannelMembers.UserId = :UserId
+			    AND Channels.TeamId = :TeamId
+			    AND Channels.Type IN ('O', 'P')
+			    AND Channels.DeleteAt = 0
+			    SEARCH_CLAUSE
+			ORDER BY DisplayName
+			LIMIT 100`
+
+		*result = s.performSearch(searchQuery, term, map[string]interface{}{"TeamId": teamId, "UserId": userId})
+	})
+}
+
 func (s SqlChannelStore) buildLIKEClause(term string) (likeClause, likeTerm string) {
 	likeTerm = term
 	searchColumns := "Name, DisplayName"
@@ -0,0 +1,438 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/mattermost/gorp"
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+const SIDEBAR_CATEGORY_SORT_ORDER_STEP = 10
+
+func (s SqlChannelStore) CreateInitialSidebarCategories(userId string, teamId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		transaction, err := s.GetMaster().Begin()
+		if err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.CreateInitialSidebarCategories", "store.sql_channel.sidebar_categories.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if created := s.createInitialSidebarCategoriesT(transaction, userId, teamId); created.Err != nil {
+			transaction.Rollback()
+
+			// The categories have the same ids every time, so saving them fails if another request saved them first
+			if count, err := s.GetMaster().SelectInt("SELECT COUNT(*) FROM SidebarCategories WHERE UserId = :UserId AND TeamId = :TeamId", map[string]interface{}{"UserId": userId, "TeamId": teamId}); err != nil || count == 0 {
+				result.Err = created.Err
+				return
+			}
+		} else if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.CreateInitialSidebarCategories", "store.sql_channel.sidebar_categories.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// The categories are read from the master since they may not have reached the replicas yet
+		if categories, err := s.getSidebarCategories(s.GetMaster(), userId, teamId); err != nil {
+			result.Err = err
+		} else {
+			result.Data = categories
+		}
+	})
+}
+
+func (s SqlChannelStore) createInitialSidebarCategoriesT(transaction *gorp.Transaction, userId string, teamId string) store.StoreResult {
+	result := store.StoreResult{}
+
+	props := map[string]interface{}{"UserId": userId, "TeamId": teamId}
+
+	if count, err := transaction.SelectInt("SELECT COUNT(*) FROM SidebarCategories WHERE UserId = :UserId AND TeamId = :TeamId", props); err != nil {
+		result.Err = model.NewAppError("SqlChannelStore.CreateInitialSidebarCategories", "store.sql_channel.sidebar_categories.get.app_error", nil, "user_id="+userId+", team_id="+teamId+", "+err.Error(), http.StatusInternalServerError)
+		return result
+	} else if count > 0 {
+		// Another request already created them
+		return result
+	}
+
+	categories := []*model.SidebarCategory{
+		{UserId: userId, TeamId: teamId, Type: model.SIDEBAR_CATEGORY_FAVORITES, DisplayName: "Favorites"},
+		{UserId: userId, TeamId: teamId, Type: model.SIDEBAR_CATEGORY_CHANNELS, DisplayName: "Channels"},
+		{UserId: userId, TeamId: teamId, Type: model.SIDEBAR_CATEGORY_DIRECT_MESSAGES, DisplayName: "Direct Messages"},
+	}
+
+	for i, category := range categories {
+		category.Id = model.DefaultSidebarCategoryId(category.Type, userId, teamId)
+		category.SortOrder = int64(i * SIDEBAR_CATEGORY_SORT_ORDER_STEP)
+		category.PreSave()
+
+		if err := transaction.Insert(category); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.CreateInitialSidebarCategories", "store.sql_channel.sidebar_categories.save.app_error", nil, "user_id="+userId+", team_id="+teamId+", "+err.Error(), http.StatusInternalServerError)
+			return result
+		}
+	}
+
+	// Move any channels that the user has already marked as a favorite into the Favorites category
+	var favoriteChannelIds []string
+	props["Category"] = model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL
+	if _, err := transaction.Select(&favoriteChannelIds,
+		`SELECT
+			Preferences.Name
+		FROM
+			Preferences
+			INNER JOIN Channels ON Channels.Id = Preferences.Name
+			INNER JOIN ChannelMembers ON ChannelMembers.ChannelId = Channels.Id AND ChannelMembers.UserId = Preferences.UserId
+		WHERE
+			Preferences.UserId = :UserId
+			AND Preferences.Category = :Category
+			AND Preferences.Value = 'true'
+			AND (Channels.TeamId = :TeamId OR Channels.TeamId = '')
+			AND Channels.DeleteAt = 0
+		ORDER BY
+			Channels.DisplayName, Channels.Name`, props); err != nil {
+		result.Err = model.NewAppError("SqlChannelStore.CreateInitialSidebarCategories", "store.sql_channel.sidebar_categories.get.app_error", nil, "user_id="+userId+", team_id="+teamId+", "+err.Error(), http.StatusInternalServerError)
+		return result
+	}
+
+	favorites := &model.SidebarCategoryWithChannels{
+		SidebarCategory: *categories[0],
+		Channels:        favoriteChannelIds,
+	}
+	result.Err = s.insertSidebarChannelsT(transaction, favorites)
+
+	return result
+}
+
+func (s SqlChannelStore) CreateSidebarCategory(category *model.SidebarCategoryWithChannels) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		category.PreSave()
+		if result.Err = category.IsValid(); result.Err != nil {
+			return
+		}
+
+		transaction, err := s.GetMaster().Begin()
+		if err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.CreateSidebarCategory", "store.sql_channel.sidebar_categories.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		*result = s.createSidebarCategoryT(transaction, category)
+
+		if result.Err != nil {
+			transaction.Rollback()
+		} else if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.CreateSidebarCategory", "store.sql_channel.sidebar_categories.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+func (s SqlChannelStore) createSidebarCategoryT(transaction *gorp.Transaction, category *model.SidebarCategoryWithChannels) store.StoreResult {
+	result := store.StoreResult{}
+
+	// New categories are added to the end of the sidebar
+	maxSortOrder, err := transaction.SelectInt("SELECT COALESCE(MAX(SortOrder), 0) FROM SidebarCategories WHERE UserId = :UserId AND TeamId = :TeamId", map[string]interface{}{"UserId": category.UserId, "TeamId": category.TeamId})
+	if err != nil {
+		result.Err = model.NewAppError("SqlChannelStore.CreateSidebarCategory", "store.sql_channel.sidebar_categories.get.app_error", nil, "user_id="+category.UserId+", team_id="+category.TeamId+", "+err.Error(), http.StatusInternalServerError)
+		return result
+	}
+	category.SortOrder = maxSortOrder + SIDEBAR_CATEGORY_SORT_ORDER_STEP
+
+	if err := transaction.Insert(&category.SidebarCategory); err != nil {
+		result.Err = model.NewAppError("SqlChannelStore.CreateSidebarCategory", "store.sql_channel.sidebar_categories.save.app_error", nil, "id="+category.Id+", "+err.Error(), http.StatusInternalServerError)
+		return result
+	}
+
+	if result.Err = s.insertSidebarChannelsT(transaction, category); result.Err != nil {
+		return result
+	}
+
+	result.Data = category
+	return result
+}
+
+// insertSidebarChannelsT places the category's channels into it in order, removing them from any
+// other category that the user has for the same team.
+func (s SqlChannelStore) insertSidebarChannelsT(transaction *gorp.Transaction, category *model.SidebarCategoryWithChannels) *model.AppError {
+	if len(category.Channels) == 0 {
+		return nil
+	}
+
+	props := map[string]interface{}{"UserId": category.UserId, "TeamId": category.TeamId}
+	idQuery := ""
+
+	for index, channelId := range category.Channels {
+		if len(idQuery) > 0 {
+			idQuery += ", "
+		}
+
+		props["channelId"+strconv.Itoa(index)] = channelId
+		idQuery += ":channelId" + strconv.Itoa(index)
+	}
+
+	if _, err := transaction.Exec(
+		`DELETE FROM
+			SidebarChannels
+		WHERE
+			UserId = :UserId
+			AND ChannelId IN (`+idQuery+`)
+			AND CategoryId IN (SELECT Id FROM SidebarCategories WHERE UserId = :UserId AND TeamId = :TeamId)`, props); err != nil {
+		return model.NewAppError("SqlChannelStore.insertSidebarChannelsT", "store.sql_channel.sidebar_categories.update.app_error", nil, "category_id="+category.Id+", "+err.Error(), http.StatusInternalServerError)
+	}
+
+	for index, channelId := range category.Channels {
+		sidebarChannel := &model.SidebarChannel{
+			ChannelId:  channelId,
+			UserId:     category.UserId,
+			CategoryId: category.Id,
+			SortOrder:  int64(index * SIDEBAR_CATEGORY_SORT_ORDER_STEP),
+		}
+
+		if err := transaction.Insert(sidebarChannel); err != nil {
+			return model.NewAppError("SqlChannelStore.insertSidebarChannelsT", "store.sql_channel.sidebar_categories.save.app_error", nil, "category_id="+category.Id+", channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+		}
+	}
+
+	return nil
+}
+
+func (s SqlChannelStore) GetSidebarCategory(categoryId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var category model.SidebarCategory
+		if err := s.GetReplica().SelectOne(&category, "SELECT * FROM SidebarCategories WHERE Id = :Id", map[string]interface{}{"Id": categoryId}); err != nil {
+			if err == sql.ErrNoRows {
+				result.Err = model.NewAppError("SqlChannelStore.GetSidebarCategory", "store.sql_channel.sidebar_categories.not_found.app_error", nil, "id="+categoryId, http.StatusNotFound)
+			} else {
+				result.Err = model.NewAppError("SqlChannelStore.GetSidebarCategory", "store.sql_channel.sidebar_categories.get.app_error", nil, "id="+categoryId+", "+err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		channelIds := []string{}
+		if _, err := s.GetReplica().Select(&channelIds, "SELECT ChannelId FROM SidebarChannels WHERE CategoryId = :CategoryId ORDER BY SortOrder", map[string]interface{}{"CategoryId": categoryId}); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.GetSidebarCategory", "store.sql_channel.sidebar_categories.get.app_error", nil, "id="+categoryId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = &model.SidebarCategoryWithChannels{
+			SidebarCategory: category,
+			Channels:        channelIds,
+		}
+	})
+}
+
+func (s SqlChannelStore) GetSidebarCategories(userId string, teamId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if categories, err := s.getSidebarCategories(s.GetReplica(), userId, teamId); err != nil {
+			result.Err = err
+		} else {
+			result.Data = categories
+		}
+	})
+}
+
+func (s SqlChannelStore) getSidebarCategories(db gorp.SqlExecutor, userId string, teamId string) (*model.OrderedSidebarCategories, *model.AppError) {
+	props := map[string]interface{}{"UserId": userId, "TeamId": teamId}
+
+	var categories []*model.SidebarCategory
+	if _, err := db.Select(&categories, "SELECT * FROM SidebarCategories WHERE UserId = :UserId AND TeamId = :TeamId ORDER BY SortOrder, Id", props); err != nil {
+		return nil, model.NewAppError("SqlChannelStore.GetSidebarCategories", "store.sql_channel.sidebar_categories.get.app_error", nil, "user_id="+userId+", team_id="+teamId+", "+err.Error(), http.StatusInternalServerError)
+	}
+
+	var sidebarChannels []*model.SidebarChannel
+	if _, err := db.Select(&sidebarChannels,
+		`SELECT
+			SidebarChannels.*
+		FROM
+			SidebarChannels
+			INNER JOIN SidebarCategories ON SidebarCategories.Id = SidebarChannels.CategoryId
+		WHERE
+			SidebarCategories.UserId = :UserId
+			AND SidebarCategories.TeamId = :TeamId
+		ORDER BY
+			SidebarChannels.SortOrder`, props); err != nil {
+		return nil, model.NewAppError("SqlChannelStore.GetSidebarCategories", "store.sql_channel.sidebar_categories.get.app_error", nil, "user_id="+userId+", team_id="+teamId+", "+err.Error(), http.StatusInternalServerError)
+	}
+
+	ordered := &model.OrderedSidebarCategories{
+		Categories: make([]*model.SidebarCategoryWithChannels, 0, len(categories)),
+		Order:      make([]string, 0, len(categories)),
+	}
+	byId := make(map[string]*model.SidebarCategoryWithChannels, len(categories))
+
+	for _, category := range categories {
+		withChannels := &model.SidebarCategoryWithChannels{
+			SidebarCategory: *category,
+			Channels:        []string{},
+		}
+
+		ordered.Categories = append(ordered.Categories, withChannels)
+		ordered.Order = append(ordered.Order, category.Id)
+		byId[category.Id] = withChannels
+	}
+
+	for _, sidebarChannel := range sidebarChannels {
+		if category, ok := byId[sidebarChannel.CategoryId]; ok {
+			category.Channels = append(category.Channels, sidebarChannel.ChannelId)
+		}
+	}
+
+	return ordered, nil
+}
+
+func (s SqlChannelStore) UpdateSidebarCategories(userId string, teamId string, categories []*model.SidebarCategoryWithChannels) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		transaction, err := s.GetMaster().Begin()
+		if err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.UpdateSidebarCategories", "store.sql_channel.sidebar_categories.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		*result = s.updateSidebarCategoriesT(transaction, userId, teamId, categories)
+
+		if result.Err != nil {
+			transaction.Rollback()
+		} else if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.UpdateSidebarCategories", "store.sql_channel.sidebar_categories.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+func (s SqlChannelStore) updateSidebarCategoriesT(transaction *gorp.Transaction, userId string, teamId string, categories []*model.SidebarCategoryWithChannels) store.StoreResult {
+	result := store.StoreResult{}
+
+	updated := make([]*model.SidebarCategoryWithChannels, 0, len(categories))
+
+	for _, category := range categories {
+		var existing model.SidebarCategory
+		if err := transaction.SelectOne(&existing, "SELECT * FROM SidebarCategories WHERE Id = :Id AND UserId = :UserId AND TeamId = :TeamId", map[string]interface{}{"Id": category.Id, "UserId": userId, "TeamId": teamId}); err != nil {
+			if err == sql.ErrNoRows {
+				result.Err = model.NewAppError("SqlChannelStore.UpdateSidebarCategories", "store.sql_channel.sidebar_categories.not_found.app_error", nil, "id="+category.Id, http.StatusNotFound)
+			} else {
+				result.Err = model.NewAppError("SqlChannelStore.UpdateSidebarCategories", "store.sql_channel.sidebar_categories.get.app_error", nil, "id="+category.Id+", "+err.Error(), http.StatusInternalServerError)
+			}
+			return result
+		}
+
+		// Only the name and the channels of a category can be changed here
+		existing.DisplayName = category.DisplayName
+		if result.Err = existing.IsValid(); result.Err != nil {
+			return result
+		}
+
+		if _, err := transaction.Update(&existing); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.UpdateSidebarCategories", "store.sql_channel.sidebar_categories.update.app_error", nil, "id="+category.Id+", "+err.Error(), http.StatusInternalServerError)
+			return result
+		}
+
+		if _, err := transaction.Exec("DELETE FROM SidebarChannels WHERE CategoryId = :CategoryId", map[string]interface{}{"CategoryId": category.Id}); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.UpdateSidebarCategories", "store.sql_channel.sidebar_categories.update.app_error", nil, "id="+category.Id+", "+err.Error(), http.StatusInternalServerError)
+			return result
+		}
+
+		withChannels := &model.SidebarCategoryWithChannels{
+			SidebarCategory: existing,
+			Channels:        category.Channels,
+		}
+		if withChannels.Channels == nil {
+			withChannels.Channels = []string{}
+		}
+
+		if result.Err = s.insertSidebarChannelsT(transaction, withChannels); result.Err != nil {
+			return result
+		}
+
+		updated = append(updated, withChannels)
+	}
+
+	result.Data = updated
+	return result
+}
+
+func (s SqlChannelStore) UpdateSidebarCategoryOrder(userId string, teamId string, categoryOrder []string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		transaction, err := s.GetMaster().Begin()
+		if err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.UpdateSidebarCategoryOrder", "store.sql_channel.sidebar_categories.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		for index, categoryId := range categoryOrder {
+			if _, err := transaction.Exec("UPDATE SidebarCategories SET SortOrder = :SortOrder WHERE Id = :Id AND UserId = :UserId AND TeamId = :TeamId", map[string]interface{}{"SortOrder": index * SIDEBAR_CATEGORY_SORT_ORDER_STEP, "Id": categoryId, "UserId": userId, "TeamId": teamId}); err != nil {
+				transaction.Rollback()
+				result.Err = model.NewAppError("SqlChannelStore.UpdateSidebarCategoryOrder", "store.sql_channel.sidebar_categories.update.app_error", nil, "id="+categoryId+", "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.UpdateSidebarCategoryOrder", "store.sql_channel.sidebar_categories.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+func (s SqlChannelStore) DeleteSidebarCategory(categoryId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		transaction, err := s.GetMaster().Begin()
+		if err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.DeleteSidebarCategory", "store.sql_channel.sidebar_categories.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		props := map[string]interface{}{"CategoryId": categoryId}
+
+		if _, err := transaction.Exec("DELETE FROM SidebarChannels WHERE CategoryId = :CategoryId", props); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlChannelStore.DeleteSidebarCategory", "store.sql_channel.sidebar_categories.delete.app_error", nil, "id="+categoryId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := transaction.Exec("DELETE FROM SidebarCategories WHERE Id = :CategoryId", props); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlChannelStore.DeleteSidebarCategory", "store.sql_channel.sidebar_categories.delete.app_error", nil, "id="+categoryId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.DeleteSidebarCategory", "store.sql_channel.sidebar_categories.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+func (s SqlChannelStore) RemoveChannelFromSidebarCategories(userId string, channelId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if _, err := s.GetMaster().Exec("DELETE FROM SidebarChannels WHERE UserId = :UserId AND ChannelId = :ChannelId", map[string]interface{}{"UserId": userId, "ChannelId": channelId}); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.RemoveChannelFromSidebarCategories", "store.sql_channel.sidebar_categories.delete.app_error", nil, "user_id="+userId+", channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+func (s SqlChannelStore) DeleteSidebarCategoriesForTeamMember(userId string, teamId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		transaction, err := s.GetMaster().Begin()
+		if err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.DeleteSidebarCategoriesForTeamMember", "store.sql_channel.sidebar_categories.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		props := map[string]interface{}{"UserId": userId, "TeamId": teamId}
+
+		if _, err := transaction.Exec("DELETE FROM SidebarChannels WHERE UserId = :UserId AND CategoryId IN (SELECT Id FROM SidebarCategories WHERE UserId = :UserId AND TeamId = :TeamId)", props); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlChannelStore.DeleteSidebarCategoriesForTeamMember", "store.sql_channel.sidebar_categories.delete.app_error", nil, "user_id="+userId+", team_id="+teamId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := transaction.Exec("DELETE FROM SidebarCategories WHERE UserId = :UserId AND TeamId = :TeamId", props); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlChannelStore.DeleteSidebarCategoriesForTeamMember", "store.sql_channel.sidebar_categories.delete.app_error", nil, "user_id="+userId+", team_id="+teamId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.DeleteSidebarCategoriesForTeamMember", "store.sql_channel.sidebar_categories.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+// channelModeration is a row recording that a moderated permission has been taken away from the guests or the
+// regular members of a channel, or from both. Permissions without a row are left to the channel's roles.
+type channelModeration struct {
+	ChannelId string
+	Name      string
+	Guests    bool
+	Members   bool
+}
+
+// GetModeratedPermissions returns the moderated permissions that have been taken away in a channel. They're checked
+// for every post and reaction, so they're cached until they're updated on any server in the cluster.
+func (s SqlChannelStore) GetModeratedPermissions(channelId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if cacheItem, ok := s.channelModerationsCache.Get(channelId); ok {
+			result.Data = cacheItem.(*model.ChannelModeratedPermissions)
+			return
+		}
+
+		var rows []*channelModeration
+		if _, err := s.GetReplica().Select(&rows, "SELECT * FROM ChannelModerations WHERE ChannelId = :ChannelId ORDER BY Name", map[string]interface{}{"ChannelId": channelId}); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.GetModeratedPermissions", "store.sql_channel.moderations.get.app_error", nil, "channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		moderated := &model.ChannelModeratedPermissions{Guests: []string{}, Members: []string{}}
+		for _, row := range rows {
+			if row.Guests {
+				moderated.Guests = append(moderated.Guests, row.Name)
+			}
+			if row.Members {
+				moderated.Members = append(moderated.Members, row.Name)
+			}
+		}
+
+		s.channelModerationsCache.AddWithDefaultExpires(channelId, moderated)
+
+		result.Data = moderated
+	})
+}
+
+func (s SqlChannelStore) UpdateModeratedPermissions(channelId string, moderated *model.ChannelModeratedPermissions) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		rows := make(map[string]*channelModeration)
+		var names []string
+		rowFor := func(name string) *channelModeration {
+			if _, ok := rows[name]; !ok {
+				rows[name] = &channelModeration{ChannelId: channelId, Name: name}
+				names = append(names, name)
+			}
+			return rows[name]
+		}
+		for _, name := range moderated.Guests {
+			rowFor(name).Guests = true
+		}
+		for _, name := range moderated.Members {
+			rowFor(name).Members = true
+		}
+
+		transaction, err := s.GetMaster().Begin()
+		if err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.UpdateModeratedPermissions", "store.sql_channel.moderations.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := transaction.Exec("DELETE FROM ChannelModerations WHERE ChannelId = :ChannelId", map[string]interface{}{"ChannelId": channelId}); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlChannelStore.UpdateModeratedPermissions", "store.sql_channel.moderations.update.app_error", nil, "channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		for _, name := range names {
+			if err := transaction.Insert(rows[name]); err != nil {
+				transaction.Rollback()
+				result.Err = model.NewAppError("SqlChannelStore.UpdateModeratedPermissions", "store.sql_channel.moderations.update.app_error", nil, "channel_id="+channelId+", name="+name+", "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.UpdateModeratedPermissions", "store.sql_channel.moderations.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		s.GetCacheProvider().Invalidate(model.CACHE_CHANNEL_MODERATIONS, channelId)
+
+		result.Data = moderated
+	})
+}
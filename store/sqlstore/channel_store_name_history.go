@@ -0,0 +1,100 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+// SaveNameHistory records one of a channel's old names, taking the name over from any other channel that used to
+// have it. Only the newest maxPerChannel names are kept for each channel.
+func (s SqlChannelStore) SaveNameHistory(history *model.ChannelNameHistory, maxPerChannel int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		history.PreSave()
+		if result.Err = history.IsValid(); result.Err != nil {
+			return
+		}
+
+		transaction, err := s.GetMaster().Begin()
+		if err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.SaveNameHistory", "store.sql_channel.name_history.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := transaction.Exec("DELETE FROM ChannelNameHistory WHERE TeamId = :TeamId AND Name = :Name", map[string]interface{}{"TeamId": history.TeamId, "Name": history.Name}); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlChannelStore.SaveNameHistory", "store.sql_channel.name_history.save.app_error", nil, "channel_id="+history.ChannelId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := transaction.Insert(history); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlChannelStore.SaveNameHistory", "store.sql_channel.name_history.save.app_error", nil, "channel_id="+history.ChannelId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var names []string
+		if _, err := transaction.Select(&names, "SELECT Name FROM ChannelNameHistory WHERE ChannelId = :ChannelId ORDER BY CreateAt DESC, Name", map[string]interface{}{"ChannelId": history.ChannelId}); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlChannelStore.SaveNameHistory", "store.sql_channel.name_history.save.app_error", nil, "channel_id="+history.ChannelId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		for i := maxPerChannel; i < len(names); i++ {
+			if _, err := transaction.Exec("DELETE FROM ChannelNameHistory WHERE ChannelId = :ChannelId AND Name = :Name", map[string]interface{}{"ChannelId": history.ChannelId, "Name": names[i]}); err != nil {
+				transaction.Rollback()
+				result.Err = model.NewAppError("SqlChannelStore.SaveNameHistory", "store.sql_channel.name_history.save.app_error", nil, "channel_id="+history.ChannelId+", "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.SaveNameHistory", "store.sql_channel.name_history.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = history
+	})
+}
+
+func (s SqlChannelStore) GetNameHistoryByName(teamId string, name string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var history model.ChannelNameHistory
+		if err := s.GetReplica().SelectOne(&history, "SELECT * FROM ChannelNameHistory WHERE TeamId = :TeamId AND Name = :Name", map[string]interface{}{"TeamId": teamId, "Name": name}); err != nil {
+			if err == sql.ErrNoRows {
+				result.Err = model.NewAppError("SqlChannelStore.GetNameHistoryByName", "store.sql_channel.name_history.get_by_name.missing.app_error", nil, "team_id="+teamId+", name="+name, http.StatusNotFound)
+			} else {
+				result.Err = model.NewAppError("SqlChannelStore.GetNameHistoryByName", "store.sql_channel.name_history.get_by_name.app_error", nil, "team_id="+teamId+", name="+name+", "+err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		result.Data = &history
+	})
+}
+
+// GetNameHistory returns the old names of a channel, starting with the one that it was most recently renamed from.
+func (s SqlChannelStore) GetNameHistory(channelId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var history []*model.ChannelNameHistory
+		if _, err := s.GetReplica().Select(&history, "SELECT * FROM ChannelNameHistory WHERE ChannelId = :ChannelId ORDER BY CreateAt DESC, Name", map[string]interface{}{"ChannelId": channelId}); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.GetNameHistory", "store.sql_channel.name_history.get.app_error", nil, "channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = history
+	})
+}
+
+func (s SqlChannelStore) DeleteNameHistory(teamId string, name string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if _, err := s.GetMaster().Exec("DELETE FROM ChannelNameHistory WHERE TeamId = :TeamId AND Name = :Name", map[string]interface{}{"TeamId": teamId, "Name": name}); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.DeleteNameHistory", "store.sql_channel.name_history.delete.app_error", nil, "team_id="+teamId+", name="+name+", "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
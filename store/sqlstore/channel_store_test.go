@@ -6,9 +6,71 @@ package sqlstore
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
 	"github.com/mattermost/mattermost-server/store/storetest"
 )
 
 func TestChannelStore(t *testing.T) {
 	StoreTest(t, storetest.TestChannelStore)
 }
+
+func TestChannelStoreReconcileMemberCounts(t *testing.T) {
+	StoreTest(t, func(t *testing.T, ss store.Store) {
+		sqlStore := ss.(*store.LayeredStore).DatabaseLayer.(SqlStore)
+
+		reconcileAll := func() int64 {
+			var corrected int64
+			afterChannelId := ""
+			for {
+				result := <-ss.Channel().ReconcileMemberCounts(afterChannelId, 10)
+				require.Nil(t, result.Err)
+
+				batch := result.Data.(*model.ChannelMemberCountsBatch)
+				corrected += batch.Corrected
+				if batch.LastChannelId == "" {
+					return corrected
+				}
+
+				require.True(t, batch.LastChannelId > afterChannelId, "should have moved on to the next batch")
+				afterChannelId = batch.LastChannelId
+			}
+		}
+
+		drifted := store.Must(ss.Channel().Save(&model.Channel{TeamId: model.NewId(), DisplayName: "Drifted", Name: "zz" + model.NewId() + "b", Type: model.CHANNEL_OPEN}, -1)).(*model.Channel)
+		correct := store.Must(ss.Channel().Save(&model.Channel{TeamId: model.NewId(), DisplayName: "Correct", Name: "zz" + model.NewId() + "b", Type: model.CHANNEL_OPEN}, -1)).(*model.Channel)
+
+		user := store.Must(ss.User().Save(&model.User{Email: model.NewId()})).(*model.User)
+		guest := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Roles: model.SYSTEM_GUEST_ROLE_ID})).(*model.User)
+		inactive := store.Must(ss.User().Save(&model.User{Email: model.NewId(), DeleteAt: model.GetMillis()})).(*model.User)
+
+		for _, channelId := range []string{drifted.Id, correct.Id} {
+			for _, userId := range []string{user.Id, guest.Id, inactive.Id} {
+				store.Must(ss.Channel().SaveMember(&model.ChannelMember{ChannelId: channelId, UserId: userId, NotifyProps: model.GetDefaultChannelNotifyProps()}))
+			}
+		}
+
+		// Start from a clean slate in case earlier tests left their channels' counts out of date
+		reconcileAll()
+
+		_, err := sqlStore.GetMaster().Exec("UPDATE Channels SET MemberCount = 42, GuestCount = 7 WHERE Id = :ChannelId", map[string]interface{}{"ChannelId": drifted.Id})
+		require.Nil(t, err)
+
+		assert.Equal(t, int64(1), reconcileAll())
+
+		for _, channelId := range []string{drifted.Id, correct.Id} {
+			result := <-ss.Channel().GetMemberCount(channelId, false)
+			require.Nil(t, result.Err)
+			assert.Equal(t, int64(2), result.Data.(int64))
+
+			result = <-ss.Channel().GetGuestCount(channelId)
+			require.Nil(t, result.Err)
+			assert.Equal(t, int64(1), result.Data.(int64))
+		}
+
+		assert.Equal(t, int64(0), reconcileAll(), "shouldn't have anything left to fix")
+	})
+}
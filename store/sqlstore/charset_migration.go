@@ -0,0 +1,367 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"context"
+	dbsql "database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const (
+	CHARSET_MIGRATION_COLLATION = "utf8mb4_general_ci"
+
+	// MYSQL_SMALL_KEY_PART_LENGTH is the most bytes of a column that InnoDB can index in tables with the COMPACT or
+	// REDUNDANT row formats, or when large index prefixes are turned off. MYSQL_LARGE_KEY_PART_LENGTH is the most
+	// otherwise.
+	MYSQL_SMALL_KEY_PART_LENGTH = 767
+	MYSQL_LARGE_KEY_PART_LENGTH = 3072
+
+	mysqlBytesPerCharacter = 4
+)
+
+// CharsetMigrationProgress is called by MigrateCharset before each table is converted, or with converted set to false
+// for tables that don't need to be.
+type CharsetMigrationProgress func(table string, done int, total int, converted bool)
+
+type charsetMigrationKeyPart struct {
+	IndexName  string
+	NonUnique  bool
+	ColumnName string
+	SubPart    dbsql.NullInt64
+	MaxLength  dbsql.NullInt64
+	Charset    dbsql.NullString
+	IndexType  string
+}
+
+// checkFullUnicode returns false if posts can't contain emoji and other characters that take four bytes in UTF-8,
+// which happens on MySQL databases that were created with the utf8 charset instead of utf8mb4.
+func (ss *SqlSupplier) checkFullUnicode() bool {
+	if ss.DriverName() != model.DATABASE_DRIVER_MYSQL {
+		return true
+	}
+
+	charset, err := ss.GetMaster().SelectStr("SELECT COALESCE(CHARACTER_SET_NAME, '') FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'Posts' AND COLUMN_NAME = 'Message'")
+	if err != nil {
+		mlog.Error(fmt.Sprintf("Unable to check the charset of the database: %v", err))
+		return true
+	}
+
+	connectionCharset, err := ss.GetMaster().SelectStr("SELECT @@character_set_connection")
+	if err != nil {
+		mlog.Error(fmt.Sprintf("Unable to check the charset of the database connection: %v", err))
+		return true
+	}
+
+	if charset != SCHEMA_EXPECTED_MYSQL_CHARSET || connectionCharset != SCHEMA_EXPECTED_MYSQL_CHARSET {
+		mlog.Warn(fmt.Sprintf("The database uses the %v charset with a %v connection instead of %v, so messages with emoji in them will be rejected or mangled. Run \"mattermost db migrate-charset --to %v\" to convert it.", charset, connectionCharset, SCHEMA_EXPECTED_MYSQL_CHARSET, SCHEMA_EXPECTED_MYSQL_CHARSET))
+		return false
+	}
+
+	return true
+}
+
+// IsFullUnicodeSupported returns false if the database was found to be unable to store characters that take four
+// bytes in UTF-8, such as emoji, when the server started.
+func (ss *SqlSupplier) IsFullUnicodeSupported() bool {
+	return ss.fullUnicode
+}
+
+// CheckCharsetSettings returns the problems with the settings of a MySQL server that stop the tables from being
+// converted to utf8mb4 or from being used properly once they are. Warnings are about settings that only affect
+// databases and tables that are created later.
+func (ss *SqlSupplier) CheckCharsetSettings() (warnings []string, err error) {
+	if ss.DriverName() != model.DATABASE_DRIVER_MYSQL {
+		return nil, fmt.Errorf("only MySQL databases need their charset to be converted")
+	}
+
+	var settings struct {
+		ConnectionCharset   string
+		ConnectionCollation string
+		ServerCharset       string
+	}
+	if err := ss.GetMaster().SelectOne(&settings, "SELECT @@character_set_connection AS ConnectionCharset, @@collation_connection AS ConnectionCollation, @@character_set_server AS ServerCharset"); err != nil {
+		return nil, err
+	}
+
+	if settings.ConnectionCharset != SCHEMA_EXPECTED_MYSQL_CHARSET {
+		return nil, fmt.Errorf("the connection to the database uses the %v charset, so emoji would still be mangled after converting the tables. Add charset=utf8mb4,utf8 to the parameters of SqlSettings.DataSource", settings.ConnectionCharset)
+	}
+
+	if !strings.HasPrefix(settings.ConnectionCollation, SCHEMA_EXPECTED_MYSQL_CHARSET+"_") {
+		return nil, fmt.Errorf("the connection to the database uses the %v collation, which doesn't match the utf8mb4 charset", settings.ConnectionCollation)
+	}
+
+	if settings.ServerCharset != SCHEMA_EXPECTED_MYSQL_CHARSET {
+		warnings = append(warnings, fmt.Sprintf("The server's default charset is %v, so that's what new databases will use. Set character-set-server to utf8mb4 in the MySQL configuration.", settings.ServerCharset))
+	}
+
+	return warnings, nil
+}
+
+// MigrateCharset converts the tables that the stores use, and the database's default charset, to utf8mb4. Each table
+// is converted in a single statement, and tables that have already been converted are skipped, so it can be run again
+// after it's interrupted. Indexes on columns that would be too long for InnoDB to index once each character takes up
+// to 4 bytes are shortened, or their tables are changed to a row format that allows them where the server supports
+// it.
+func (ss *SqlSupplier) MigrateCharset(progress CharsetMigrationProgress) error {
+	if _, err := ss.CheckCharsetSettings(); err != nil {
+		return err
+	}
+
+	tables, err := ss.charsetMigrationOrder()
+	if err != nil {
+		return err
+	}
+
+	largePrefix, err := ss.supportsLargeIndexPrefixes()
+	if err != nil {
+		return err
+	}
+
+	// Foreign keys can't be checked while the columns on each side of them have different charsets, so they're turned
+	// off on a connection of its own
+	conn, err := ss.GetMaster().Db.Conn(context.Background())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(context.Background(), "SET foreign_key_checks = 0"); err != nil {
+		return err
+	}
+
+	for i, table := range tables {
+		query, err := ss.charsetMigrationQuery(table, largePrefix)
+		if err != nil {
+			return err
+		}
+
+		if progress != nil {
+			progress(table, i+1, len(tables), query != "")
+		}
+
+		if query == "" {
+			continue
+		}
+
+		if _, err := conn.ExecContext(context.Background(), query); err != nil {
+			return fmt.Errorf("unable to convert %v: %v", table, err)
+		}
+	}
+
+	if _, err := conn.ExecContext(context.Background(), "ALTER DATABASE CHARACTER SET "+SCHEMA_EXPECTED_MYSQL_CHARSET+" COLLATE "+CHARSET_MIGRATION_COLLATION); err != nil {
+		return fmt.Errorf("unable to change the default charset of the database: %v", err)
+	}
+
+	return nil
+}
+
+// charsetMigrationOrder returns the tables that the stores use that exist in the database, with tables that foreign
+// keys refer to before the tables that the keys are in.
+func (ss *SqlSupplier) charsetMigrationOrder() ([]string, error) {
+	expected, err := ss.expectedTables()
+	if err != nil {
+		return nil, err
+	}
+
+	var existing []string
+	if _, err := ss.GetMaster().Select(&existing, "SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE'"); err != nil {
+		return nil, err
+	}
+
+	exists := map[string]string{}
+	for _, table := range existing {
+		exists[strings.ToLower(table)] = table
+	}
+
+	var tables []string
+	for _, table := range expected {
+		if name, ok := exists[strings.ToLower(table.TableName)]; ok {
+			tables = append(tables, name)
+		}
+	}
+	sort.Strings(tables)
+
+	var references []struct {
+		TableName           string
+		ReferencedTableName string
+	}
+	if _, err := ss.GetMaster().Select(&references, "SELECT TABLE_NAME AS TableName, REFERENCED_TABLE_NAME AS ReferencedTableName FROM information_schema.REFERENTIAL_CONSTRAINTS WHERE CONSTRAINT_SCHEMA = DATABASE()"); err != nil {
+		return nil, err
+	}
+
+	dependencies := map[string][]string{}
+	for _, reference := range references {
+		if reference.TableName != reference.ReferencedTableName {
+			dependencies[reference.TableName] = append(dependencies[reference.TableName], reference.ReferencedTableName)
+		}
+	}
+
+	ordered := make([]string, 0, len(tables))
+	visited := map[string]bool{}
+	var visit func(table string)
+	visit = func(table string) {
+		if visited[table] {
+			return
+		}
+		visited[table] = true
+
+		// Tables that the stores don't use are still converted when their foreign keys refer to them so that both
+		// sides of the keys match
+		for _, dependency := range dependencies[table] {
+			if _, ok := exists[strings.ToLower(dependency)]; ok {
+				visit(dependency)
+			}
+		}
+
+		ordered = append(ordered, table)
+	}
+
+	for _, table := range tables {
+		visit(table)
+	}
+
+	return ordered, nil
+}
+
+func (ss *SqlSupplier) supportsLargeIndexPrefixes() (bool, error) {
+	var variables []struct {
+		Variable_name string
+		Value         string
+	}
+	if _, err := ss.GetMaster().Select(&variables, "SHOW VARIABLES LIKE 'innodb_large_prefix'"); err != nil {
+		return false, err
+	}
+
+	// The setting was removed in MySQL 8.0 when large prefixes became the only option
+	return len(variables) == 0 || strings.EqualFold(variables[0].Value, "ON") || variables[0].Value == "1", nil
+}
+
+// charsetMigrationQuery returns the statement that converts a table to utf8mb4, or an empty string if it already
+// uses it.
+func (ss *SqlSupplier) charsetMigrationQuery(table string, largePrefix bool) (string, error) {
+	var info struct {
+		Collation string
+		RowFormat string
+	}
+	if err := ss.GetMaster().SelectOne(&info, "SELECT COALESCE(TABLE_COLLATION, '') AS Collation, COALESCE(ROW_FORMAT, '') AS RowFormat FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?", table); err != nil {
+		return "", err
+	}
+
+	otherColumns, err := ss.GetMaster().SelectInt("SELECT COUNT(*) FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND CHARACTER_SET_NAME IS NOT NULL AND CHARACTER_SET_NAME != ?", table, SCHEMA_EXPECTED_MYSQL_CHARSET)
+	if err != nil {
+		return "", err
+	}
+
+	if strings.HasPrefix(info.Collation, SCHEMA_EXPECTED_MYSQL_CHARSET+"_") && otherColumns == 0 {
+		return "", nil
+	}
+
+	var keyParts []*charsetMigrationKeyPart
+	if _, err := ss.GetMaster().Select(&keyParts, `
+		SELECT
+			s.INDEX_NAME AS IndexName,
+			s.NON_UNIQUE AS NonUnique,
+			s.COLUMN_NAME AS ColumnName,
+			s.SUB_PART AS SubPart,
+			c.CHARACTER_MAXIMUM_LENGTH AS MaxLength,
+			c.CHARACTER_SET_NAME AS Charset,
+			s.INDEX_TYPE AS IndexType
+		FROM
+			information_schema.STATISTICS s
+			JOIN information_schema.COLUMNS c ON c.TABLE_SCHEMA = s.TABLE_SCHEMA AND c.TABLE_NAME = s.TABLE_NAME AND c.COLUMN_NAME = s.COLUMN_NAME
+		WHERE
+			s.TABLE_SCHEMA = DATABASE()
+			AND s.TABLE_NAME = ?
+		ORDER BY
+			s.INDEX_NAME, s.SEQ_IN_INDEX`, table); err != nil {
+		return "", err
+	}
+
+	largeRowFormat := strings.EqualFold(info.RowFormat, "Dynamic") || strings.EqualFold(info.RowFormat, "Compressed")
+
+	tooLong := map[string]bool{}
+	for _, part := range keyParts {
+		if part.IndexType == "BTREE" && keyPartLength(part) > MYSQL_SMALL_KEY_PART_LENGTH {
+			tooLong[part.IndexName] = true
+		}
+	}
+
+	var changes []string
+	maxKeyPartLength := MYSQL_SMALL_KEY_PART_LENGTH
+	if largePrefix && (largeRowFormat || len(tooLong) > 0) {
+		maxKeyPartLength = MYSQL_LARGE_KEY_PART_LENGTH
+		if !largeRowFormat {
+			changes = append(changes, "ROW_FORMAT=DYNAMIC")
+		}
+	}
+
+	// Indexes that are still too long are rebuilt on prefixes of their columns, which can't be done for unique ones
+	// without changing what they consider to be duplicates
+	var indexName string
+	var columns []string
+	var shortened bool
+	rebuild := func() error {
+		if !shortened {
+			return nil
+		}
+
+		for _, part := range keyParts {
+			if part.IndexName == indexName && !part.NonUnique {
+				return fmt.Errorf("the unique index %v on %v would be too long for InnoDB to index with utf8mb4. Turn on innodb_large_prefix and try again", indexName, table)
+			}
+		}
+
+		changes = append(changes, "DROP INDEX "+indexName, "ADD INDEX "+indexName+" ("+strings.Join(columns, ", ")+")")
+		return nil
+	}
+
+	for _, part := range keyParts {
+		if part.IndexName != indexName {
+			if err := rebuild(); err != nil {
+				return "", err
+			}
+			indexName, columns, shortened = part.IndexName, nil, false
+		}
+
+		column := part.ColumnName
+		if part.IndexType == "BTREE" && keyPartLength(part) > maxKeyPartLength {
+			column += fmt.Sprintf("(%d)", maxKeyPartLength/mysqlBytesPerCharacter)
+			shortened = true
+		} else if part.SubPart.Valid {
+			column += fmt.Sprintf("(%d)", part.SubPart.Int64)
+		}
+		columns = append(columns, column)
+	}
+	if err := rebuild(); err != nil {
+		return "", err
+	}
+
+	changes = append(changes, "CONVERT TO CHARACTER SET "+SCHEMA_EXPECTED_MYSQL_CHARSET+" COLLATE "+CHARSET_MIGRATION_COLLATION)
+
+	return "ALTER TABLE " + table + " " + strings.Join(changes, ", "), nil
+}
+
+// keyPartLength returns how many bytes of a column an index would need once every character can take up to 4 bytes.
+func keyPartLength(part *charsetMigrationKeyPart) int {
+	if !part.Charset.Valid {
+		return 0
+	}
+
+	if part.SubPart.Valid {
+		return int(part.SubPart.Int64) * mysqlBytesPerCharacter
+	}
+
+	if part.MaxLength.Valid {
+		return int(part.MaxLength.Int64) * mysqlBytesPerCharacter
+	}
+
+	return 0
+}
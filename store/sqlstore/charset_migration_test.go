@@ -0,0 +1,151 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	dbsql "database/sql"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+// newUtf8Database creates a database with the tables that the stores use in it, and then converts them to utf8 like
+// they would be on servers that were installed before utf8mb4 was used.
+func newUtf8Database(t *testing.T, settings *model.SqlSettings) (*model.SqlSettings, func()) {
+	config, err := mysql.ParseDSN(*settings.DataSource)
+	require.Nil(t, err)
+
+	// The test user can only use the test database, so the new one is created as root
+	config.User = "root"
+	config.DBName = ""
+	root, err := dbsql.Open("mysql", config.FormatDSN())
+	require.Nil(t, err)
+
+	config.DBName = "mattermost_utf8_" + model.NewId()[:8]
+	_, err = root.Exec("CREATE DATABASE " + config.DBName + " CHARACTER SET utf8 COLLATE utf8_general_ci")
+	require.Nil(t, err)
+
+	utf8Settings := *settings
+	utf8Settings.DataSource = model.NewString(config.FormatDSN())
+	utf8Settings.DataSourceReplicas = []string{}
+	utf8Settings.DataSourceSearchReplicas = []string{}
+
+	supplier := NewSqlSupplier(utf8Settings, nil, nil)
+	defer supplier.Close()
+
+	var tables []string
+	_, err = supplier.GetMaster().Select(&tables, "SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE()")
+	require.Nil(t, err)
+	for _, table := range tables {
+		_, err = supplier.GetMaster().ExecNoTimeout("ALTER TABLE " + table + " CONVERT TO CHARACTER SET utf8 COLLATE utf8_general_ci")
+		require.Nil(t, err)
+	}
+
+	return &utf8Settings, func() {
+		root.Exec("DROP DATABASE " + config.DBName)
+		root.Close()
+	}
+}
+
+func TestMigrateCharset(t *testing.T) {
+	for _, st := range storeTypes {
+		st := st
+		if *st.Settings.DriverName != model.DATABASE_DRIVER_MYSQL {
+			continue
+		}
+
+		t.Run(st.Name, func(t *testing.T) {
+			settings, tearDown := newUtf8Database(t, st.Settings)
+			defer tearDown()
+
+			message := "a post with emoji 🙂👍 in it"
+			newPost := func() *model.Post {
+				return &model.Post{ChannelId: model.NewId(), UserId: model.NewId(), Message: message}
+			}
+
+			t.Run("before migrating", func(t *testing.T) {
+				supplier := NewSqlSupplier(*settings, nil, nil)
+				defer supplier.Close()
+
+				assert.False(t, supplier.IsFullUnicodeSupported())
+
+				result := <-supplier.Post().Save(newPost())
+				if result.Err == nil {
+					saved := store.Must(supplier.Post().GetSingle(result.Data.(*model.Post).Id)).(*model.Post)
+					assert.NotEqual(t, message, saved.Message, "the emoji shouldn't have been saved")
+				}
+
+				issues, err := supplier.CheckSchema()
+				require.Nil(t, err)
+
+				var charsetIssues int
+				for _, issue := range issues {
+					if issue.Kind == SCHEMA_ISSUE_CHARSET {
+						charsetIssues++
+					}
+				}
+				assert.NotZero(t, charsetIssues, "doctor should report the utf8 tables")
+			})
+
+			supplier := NewSqlSupplierForSchemaCheck(*settings)
+			defer supplier.Close()
+
+			_, err := supplier.CheckCharsetSettings()
+			require.Nil(t, err)
+
+			var converted []string
+			require.Nil(t, supplier.MigrateCharset(func(table string, done int, total int, wasConverted bool) {
+				assert.True(t, done <= total)
+				if wasConverted {
+					converted = append(converted, table)
+				}
+			}))
+			assert.Contains(t, converted, "Posts")
+			assert.Contains(t, converted, "Users")
+
+			converted = nil
+			require.Nil(t, supplier.MigrateCharset(func(table string, done int, total int, wasConverted bool) {
+				if wasConverted {
+					converted = append(converted, table)
+				}
+			}))
+			assert.Empty(t, converted, "tables that were already converted should be skipped")
+
+			issues, err := supplier.CheckSchema()
+			require.Nil(t, err)
+			for _, issue := range issues {
+				assert.NotEqual(t, SCHEMA_ISSUE_CHARSET, issue.Kind, issue.String())
+			}
+
+			t.Run("after migrating", func(t *testing.T) {
+				supplier := NewSqlSupplier(*settings, nil, nil)
+				defer supplier.Close()
+
+				assert.True(t, supplier.IsFullUnicodeSupported())
+
+				post := store.Must(supplier.Post().Save(newPost())).(*model.Post)
+				saved := store.Must(supplier.Post().GetSingle(post.Id)).(*model.Post)
+				assert.Equal(t, message, saved.Message)
+			})
+		})
+	}
+}
+
+func TestMigrateCharsetPostgres(t *testing.T) {
+	for _, st := range storeTypes {
+		if *st.Settings.DriverName != model.DATABASE_DRIVER_POSTGRES {
+			continue
+		}
+
+		supplier := NewSqlSupplierForSchemaCheck(*st.Settings)
+		defer supplier.Close()
+
+		assert.Error(t, supplier.MigrateCharset(nil), "only MySQL databases should be converted")
+	}
+}
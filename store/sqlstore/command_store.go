@@ -32,6 +32,8 @@ func NewSqlCommandStore(sqlStore SqlStore) store.CommandStore {
 		tableo.ColMap("AutoCompleteHint").SetMaxSize(1024)
 		tableo.ColMap("DisplayName").SetMaxSize(64)
 		tableo.ColMap("Description").SetMaxSize(128)
+		tableo.ColMap("SigningSecret").SetMaxSize(64)
+		tableo.ColMap("SigningAlgorithm").SetMaxSize(16)
 	}
 
 	return s
@@ -214,6 +214,62 @@ func (s SqlComplianceStore) ComplianceExport(job *model.Compliance) store.StoreC
 	})
 }
 
+// ExportPosts returns a batch of the posts made from startTime through endTime, including deleted posts and the
+// versions of posts that were replaced by edits. The posts are ordered by CreateAt and Id, and the batch starts
+// after the post with afterCreateAt and afterId, so a batch is fetched by passing the last post of the one before.
+func (s SqlComplianceStore) ExportPosts(startTime int64, endTime int64, afterCreateAt int64, afterId string, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if afterCreateAt < startTime {
+			afterCreateAt = startTime
+			afterId = ""
+		}
+
+		props := map[string]interface{}{"EndTime": endTime, "AfterCreateAt": afterCreateAt, "AfterId": afterId, "Limit": limit}
+		query :=
+			`SELECT
+				COALESCE(Teams.Name, '') AS TeamName,
+				COALESCE(Teams.DisplayName, '') AS TeamDisplayName,
+				Channels.Id AS ChannelId,
+				Channels.Name AS ChannelName,
+				Channels.DisplayName AS ChannelDisplayName,
+				Channels.Type AS ChannelType,
+				Posts.UserId AS UserId,
+				COALESCE(Users.Username, '') AS UserUsername,
+				COALESCE(Users.Email, '') AS UserEmail,
+				Posts.Id AS PostId,
+				Posts.CreateAt AS PostCreateAt,
+				Posts.UpdateAt AS PostUpdateAt,
+				Posts.DeleteAt AS PostDeleteAt,
+				Posts.RootId AS PostRootId,
+				Posts.OriginalId AS PostOriginalId,
+				Posts.Message AS PostMessage,
+				Posts.Type AS PostType,
+				Posts.Props AS PostProps,
+				Posts.FileIds AS PostFileIds,
+				CASE
+					WHEN EXISTS (SELECT 1 FROM Posts AS Versions WHERE Versions.OriginalId = Posts.Id) THEN 1
+					ELSE 0
+				END AS PostIsEdited
+			FROM
+				Posts
+				INNER JOIN Channels ON Posts.ChannelId = Channels.Id
+				LEFT OUTER JOIN Teams ON Channels.TeamId = Teams.Id
+				LEFT OUTER JOIN Users ON Posts.UserId = Users.Id
+			WHERE
+				(Posts.CreateAt > :AfterCreateAt OR (Posts.CreateAt = :AfterCreateAt AND Posts.Id > :AfterId))
+				AND Posts.CreateAt <= :EndTime
+			ORDER BY Posts.CreateAt, Posts.Id
+			LIMIT :Limit`
+
+		var posts []*model.ComplianceExportPost
+		if _, err := s.GetReplica().Select(&posts, query, props); err != nil {
+			result.Err = model.NewAppError("SqlComplianceStore.ExportPosts", "store.sql_compliance.export_posts.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = posts
+		}
+	})
+}
+
 func (s SqlComplianceStore) MessageExport(after int64, limit int) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		props := map[string]interface{}{"StartTime": after, "Limit": limit}
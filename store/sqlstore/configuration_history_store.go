@@ -0,0 +1,165 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"database/sql"
+	"net/http"
+	"sort"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+type SqlConfigurationHistoryStore struct {
+	SqlStore
+}
+
+func NewSqlConfigurationHistoryStore(sqlStore SqlStore) store.ConfigurationHistoryStore {
+	s := &SqlConfigurationHistoryStore{sqlStore}
+
+	for _, db := range sqlStore.GetAllConns() {
+		table := db.AddTableWithName(model.ConfigurationHistory{}, "ConfigurationHistory").SetKeys(false, "Id")
+		table.ColMap("Id").SetMaxSize(26)
+		table.ColMap("UserId").SetMaxSize(26)
+		table.ColMap("Source").SetMaxSize(32)
+		table.ColMap("Summary").SetMaxSize(model.CONFIGURATION_HISTORY_SUMMARY_MAX_RUNES)
+		table.ColMap("Config").SetMaxSize(model.CONFIGURATION_HISTORY_CONFIG_MAX_SIZE)
+		table.ColMap("Secrets").SetMaxSize(model.CONFIGURATION_HISTORY_CONFIG_MAX_SIZE)
+	}
+
+	return s
+}
+
+func (s SqlConfigurationHistoryStore) CreateIndexesIfNotExists() {
+	s.CreateIndexIfNotExists("idx_configuration_history_create_at", "ConfigurationHistory", "CreateAt")
+}
+
+func (s SqlConfigurationHistoryStore) Save(history *model.ConfigurationHistory) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		history.PreSave()
+
+		if result.Err = history.IsValid(); result.Err != nil {
+			return
+		}
+
+		if err := s.GetMaster().Insert(history); err != nil {
+			result.Err = model.NewAppError("SqlConfigurationHistoryStore.Save", "store.sql_configuration_history.save.app_error", nil, "id="+history.Id+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = history
+		}
+	})
+}
+
+func (s SqlConfigurationHistoryStore) Get(id string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		history := model.ConfigurationHistory{}
+
+		if err := s.GetReplica().SelectOne(&history, "SELECT * FROM ConfigurationHistory WHERE Id = :Id", map[string]interface{}{"Id": id}); err != nil {
+			if err == sql.ErrNoRows {
+				result.Err = model.NewAppError("SqlConfigurationHistoryStore.Get", "store.sql_configuration_history.get.app_error", nil, "id="+id+", "+err.Error(), http.StatusNotFound)
+			} else {
+				result.Err = model.NewAppError("SqlConfigurationHistoryStore.Get", "store.sql_configuration_history.get.app_error", nil, "id="+id+", "+err.Error(), http.StatusInternalServerError)
+			}
+		} else {
+			result.Data = &history
+		}
+	})
+}
+
+// GetPage returns the saved versions of the config from newest to oldest.
+func (s SqlConfigurationHistoryStore) GetPage(offset int, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var list []*model.ConfigurationHistory
+
+		if _, err := s.GetReplica().Select(&list, "SELECT * FROM ConfigurationHistory ORDER BY CreateAt DESC, Id DESC LIMIT :Limit OFFSET :Offset",
+			map[string]interface{}{"Limit": limit, "Offset": offset}); err != nil {
+			result.Err = model.NewAppError("SqlConfigurationHistoryStore.GetPage", "store.sql_configuration_history.get_page.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = list
+		}
+	})
+}
+
+// GetPrevious returns the newest version of the config that was saved before the given one.
+func (s SqlConfigurationHistoryStore) GetPrevious(history *model.ConfigurationHistory) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var list []*model.ConfigurationHistory
+
+		if _, err := s.GetReplica().Select(&list,
+			`SELECT * FROM ConfigurationHistory
+			WHERE CreateAt < :CreateAt OR (CreateAt = :CreateAt AND Id < :Id)
+			ORDER BY CreateAt DESC, Id DESC LIMIT 1`,
+			map[string]interface{}{"CreateAt": history.CreateAt, "Id": history.Id}); err != nil {
+			result.Err = model.NewAppError("SqlConfigurationHistoryStore.GetPrevious", "store.sql_configuration_history.get_previous.app_error", nil, "id="+history.Id+", "+err.Error(), http.StatusInternalServerError)
+		} else if len(list) == 0 {
+			result.Err = model.NewAppError("SqlConfigurationHistoryStore.GetPrevious", "store.sql_configuration_history.get_previous.app_error", nil, "id="+history.Id, http.StatusNotFound)
+		} else {
+			result.Data = list[0]
+		}
+	})
+}
+
+// UpdateSecrets replaces the secrets of the versions of the config with the given ids in a single transaction, so
+// either every version is updated or none are.
+func (s SqlConfigurationHistoryStore) UpdateSecrets(secrets map[string]string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		ids := make([]string, 0, len(secrets))
+		for id := range secrets {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		transaction, err := s.GetMaster().Begin()
+		if err != nil {
+			result.Err = model.NewAppError("SqlConfigurationHistoryStore.UpdateSecrets", "store.sql_configuration_history.update_secrets.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		for _, id := range ids {
+			if _, err := transaction.Exec("UPDATE ConfigurationHistory SET Secrets = :Secrets WHERE Id = :Id", map[string]interface{}{"Secrets": secrets[id], "Id": id}); err != nil {
+				transaction.Rollback()
+				result.Err = model.NewAppError("SqlConfigurationHistoryStore.UpdateSecrets", "store.sql_configuration_history.update_secrets.app_error", nil, "id="+id+", "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlConfigurationHistoryStore.UpdateSecrets", "store.sql_configuration_history.update_secrets.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// PermanentDeleteAllButLatest deletes every version of the config other than the newest ones, returning how many
+// were deleted.
+func (s SqlConfigurationHistoryStore) PermanentDeleteAllButLatest(keep int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		result.Data = int64(0)
+
+		// The oldest version to keep is found first, since MySQL doesn't allow a limit in a subquery of a delete
+		var oldest []*model.ConfigurationHistory
+		if _, err := s.GetMaster().Select(&oldest, "SELECT * FROM ConfigurationHistory ORDER BY CreateAt DESC, Id DESC LIMIT 1 OFFSET :Offset",
+			map[string]interface{}{"Offset": keep - 1}); err != nil {
+			result.Err = model.NewAppError("SqlConfigurationHistoryStore.PermanentDeleteAllButLatest", "store.sql_configuration_history.permanent_delete_all_but_latest.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		} else if len(oldest) == 0 {
+			return
+		}
+
+		sqlResult, err := s.GetMaster().Exec("DELETE FROM ConfigurationHistory WHERE CreateAt < :CreateAt OR (CreateAt = :CreateAt AND Id < :Id)",
+			map[string]interface{}{"CreateAt": oldest[0].CreateAt, "Id": oldest[0].Id})
+		if err != nil {
+			result.Err = model.NewAppError("SqlConfigurationHistoryStore.PermanentDeleteAllButLatest", "store.sql_configuration_history.permanent_delete_all_but_latest.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rowsAffected, err := sqlResult.RowsAffected()
+		if err != nil {
+			result.Err = model.NewAppError("SqlConfigurationHistoryStore.PermanentDeleteAllButLatest", "store.sql_configuration_history.permanent_delete_all_but_latest.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = rowsAffected
+	})
+}
@@ -0,0 +1,262 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+type SqlCustomGroupStore struct {
+	SqlStore
+}
+
+func NewSqlCustomGroupStore(sqlStore SqlStore) store.CustomGroupStore {
+	s := &SqlCustomGroupStore{
+		SqlStore: sqlStore,
+	}
+
+	for _, db := range sqlStore.GetAllConns() {
+		tableGroups := db.AddTableWithName(model.CustomGroup{}, "CustomGroups").SetKeys(false, "Id")
+		tableGroups.ColMap("Id").SetMaxSize(26)
+		tableGroups.ColMap("CreatorId").SetMaxSize(26)
+		tableGroups.ColMap("Name").SetMaxSize(model.USER_NAME_MAX_LENGTH).SetUnique(true)
+		tableGroups.ColMap("DisplayName").SetMaxSize(model.CUSTOM_GROUP_DISPLAY_NAME_MAX_RUNES)
+		tableGroups.ColMap("Description").SetMaxSize(model.CUSTOM_GROUP_DESCRIPTION_MAX_RUNES)
+
+		tableMembers := db.AddTableWithName(model.GroupMember{}, "GroupMembers").SetKeys(false, "GroupId", "UserId")
+		tableMembers.ColMap("GroupId").SetMaxSize(26)
+		tableMembers.ColMap("UserId").SetMaxSize(26)
+	}
+
+	return s
+}
+
+func (s SqlCustomGroupStore) CreateIndexesIfNotExists() {
+	s.CreateIndexIfNotExists("idx_groupmembers_user_id", "GroupMembers", "UserId")
+}
+
+func (s SqlCustomGroupStore) Save(group *model.CustomGroup) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if len(group.Id) > 0 {
+			result.Err = model.NewAppError("SqlCustomGroupStore.Save", "store.sql_custom_group.save.existing.app_error", nil, "id="+group.Id, http.StatusBadRequest)
+			return
+		}
+
+		group.PreSave()
+		if result.Err = group.IsValid(); result.Err != nil {
+			return
+		}
+
+		if err := s.GetMaster().Insert(group); err != nil {
+			if IsUniqueConstraintError(err, []string{"Name", "customgroups_name_key"}) {
+				result.Err = model.NewAppError("SqlCustomGroupStore.Save", "store.sql_custom_group.save.name_exists.app_error", nil, "name="+group.Name+", "+err.Error(), http.StatusBadRequest)
+			} else {
+				result.Err = model.NewAppError("SqlCustomGroupStore.Save", "store.sql_custom_group.save.app_error", nil, "id="+group.Id+", "+err.Error(), http.StatusInternalServerError)
+			}
+		} else {
+			result.Data = group
+		}
+	})
+}
+
+func (s SqlCustomGroupStore) Update(group *model.CustomGroup) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		group.PreUpdate()
+		if result.Err = group.IsValid(); result.Err != nil {
+			return
+		}
+
+		if count, err := s.GetMaster().Update(group); err != nil {
+			if IsUniqueConstraintError(err, []string{"Name", "customgroups_name_key"}) {
+				result.Err = model.NewAppError("SqlCustomGroupStore.Update", "store.sql_custom_group.save.name_exists.app_error", nil, "name="+group.Name+", "+err.Error(), http.StatusBadRequest)
+			} else {
+				result.Err = model.NewAppError("SqlCustomGroupStore.Update", "store.sql_custom_group.update.app_error", nil, "id="+group.Id+", "+err.Error(), http.StatusInternalServerError)
+			}
+		} else if count != 1 {
+			result.Err = model.NewAppError("SqlCustomGroupStore.Update", "store.sql_custom_group.get.missing.app_error", nil, "id="+group.Id, http.StatusNotFound)
+		} else {
+			result.Data = group
+		}
+	})
+}
+
+func (s SqlCustomGroupStore) Get(groupId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var group model.CustomGroup
+		if err := s.GetReplica().SelectOne(&group, "SELECT * FROM CustomGroups WHERE Id = :Id", map[string]interface{}{"Id": groupId}); err != nil {
+			if err == sql.ErrNoRows {
+				result.Err = model.NewAppError("SqlCustomGroupStore.Get", "store.sql_custom_group.get.missing.app_error", nil, "id="+groupId, http.StatusNotFound)
+			} else {
+				result.Err = model.NewAppError("SqlCustomGroupStore.Get", "store.sql_custom_group.get.app_error", nil, "id="+groupId+", "+err.Error(), http.StatusInternalServerError)
+			}
+		} else {
+			result.Data = &group
+		}
+	})
+}
+
+func (s SqlCustomGroupStore) GetByName(name string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var group model.CustomGroup
+		if err := s.GetReplica().SelectOne(&group, "SELECT * FROM CustomGroups WHERE Name = :Name", map[string]interface{}{"Name": strings.ToLower(name)}); err != nil {
+			if err == sql.ErrNoRows {
+				result.Err = model.NewAppError("SqlCustomGroupStore.GetByName", "store.sql_custom_group.get.missing.app_error", nil, "name="+name, http.StatusNotFound)
+			} else {
+				result.Err = model.NewAppError("SqlCustomGroupStore.GetByName", "store.sql_custom_group.get.app_error", nil, "name="+name+", "+err.Error(), http.StatusInternalServerError)
+			}
+		} else {
+			result.Data = &group
+		}
+	})
+}
+
+func (s SqlCustomGroupStore) GetByNames(names []string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		groups := []*model.CustomGroup{}
+		if len(names) == 0 {
+			result.Data = groups
+			return
+		}
+
+		props := make(map[string]interface{})
+		nameQuery := ""
+		for i, name := range names {
+			if len(nameQuery) > 0 {
+				nameQuery += ", "
+			}
+
+			props["name"+strconv.Itoa(i)] = strings.ToLower(name)
+			nameQuery += ":name" + strconv.Itoa(i)
+		}
+
+		if _, err := s.GetReplica().Select(&groups, "SELECT * FROM CustomGroups WHERE Name IN ("+nameQuery+")", props); err != nil {
+			result.Err = model.NewAppError("SqlCustomGroupStore.GetByNames", "store.sql_custom_group.get.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = groups
+		}
+	})
+}
+
+func (s SqlCustomGroupStore) GetAll(offset int, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		groups := []*model.CustomGroup{}
+		if _, err := s.GetReplica().Select(&groups, "SELECT * FROM CustomGroups ORDER BY Name LIMIT :Limit OFFSET :Offset", map[string]interface{}{"Limit": limit, "Offset": offset}); err != nil {
+			result.Err = model.NewAppError("SqlCustomGroupStore.GetAll", "store.sql_custom_group.get.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = groups
+		}
+	})
+}
+
+// Search returns the groups whose name or display name starts with the given term.
+func (s SqlCustomGroupStore) Search(term string, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		term = strings.ToLower(strings.TrimPrefix(term, "@"))
+
+		for _, c := range ignoreLikeSearchChar {
+			term = strings.Replace(term, c, "", -1)
+		}
+
+		for _, c := range escapeLikeSearchChar {
+			term = strings.Replace(term, c, "*"+c, -1)
+		}
+
+		groups := []*model.CustomGroup{}
+		if _, err := s.GetReplica().Select(&groups,
+			`SELECT
+				*
+			FROM
+				CustomGroups
+			WHERE
+				Name LIKE :Term escape '*'
+				OR LOWER(DisplayName) LIKE :Term escape '*'
+			ORDER BY
+				Name
+			LIMIT :Limit`, map[string]interface{}{"Term": term + "%", "Limit": limit}); err != nil {
+			result.Err = model.NewAppError("SqlCustomGroupStore.Search", "store.sql_custom_group.search.app_error", nil, "term="+term+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = groups
+		}
+	})
+}
+
+func (s SqlCustomGroupStore) Delete(groupId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		transaction, err := s.GetMaster().Begin()
+		if err != nil {
+			result.Err = model.NewAppError("SqlCustomGroupStore.Delete", "store.sql_custom_group.delete.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		props := map[string]interface{}{"GroupId": groupId}
+
+		if _, err := transaction.Exec("DELETE FROM GroupMembers WHERE GroupId = :GroupId", props); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlCustomGroupStore.Delete", "store.sql_custom_group.delete.app_error", nil, "id="+groupId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := transaction.Exec("DELETE FROM CustomGroups WHERE Id = :GroupId", props); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlCustomGroupStore.Delete", "store.sql_custom_group.delete.app_error", nil, "id="+groupId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlCustomGroupStore.Delete", "store.sql_custom_group.delete.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+func (s SqlCustomGroupStore) SaveMember(member *model.GroupMember) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		member.PreSave()
+		if result.Err = member.IsValid(); result.Err != nil {
+			return
+		}
+
+		if err := s.GetMaster().Insert(member); err != nil {
+			if IsUniqueConstraintError(err, []string{"GroupId", "groupmembers_pkey", "PRIMARY"}) {
+				result.Err = model.NewAppError("SqlCustomGroupStore.SaveMember", "store.sql_custom_group.save_member.exists.app_error", nil, "group_id="+member.GroupId+", user_id="+member.UserId+", "+err.Error(), http.StatusBadRequest)
+			} else {
+				result.Err = model.NewAppError("SqlCustomGroupStore.SaveMember", "store.sql_custom_group.save_member.app_error", nil, "group_id="+member.GroupId+", user_id="+member.UserId+", "+err.Error(), http.StatusInternalServerError)
+			}
+		} else {
+			result.Data = member
+		}
+	})
+}
+
+func (s SqlCustomGroupStore) RemoveMember(groupId string, userId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if _, err := s.GetMaster().Exec("DELETE FROM GroupMembers WHERE GroupId = :GroupId AND UserId = :UserId", map[string]interface{}{"GroupId": groupId, "UserId": userId}); err != nil {
+			result.Err = model.NewAppError("SqlCustomGroupStore.RemoveMember", "store.sql_custom_group.remove_member.app_error", nil, "group_id="+groupId+", user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+func (s SqlCustomGroupStore) GetMembers(groupId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		members := []*model.GroupMember{}
+		if _, err := s.GetReplica().Select(&members, "SELECT * FROM GroupMembers WHERE GroupId = :GroupId ORDER BY CreateAt", map[string]interface{}{"GroupId": groupId}); err != nil {
+			result.Err = model.NewAppError("SqlCustomGroupStore.GetMembers", "store.sql_custom_group.get_members.app_error", nil, "group_id="+groupId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = members
+		}
+	})
+}
+
+func (s SqlCustomGroupStore) PermanentDeleteMembersByUser(userId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if _, err := s.GetMaster().Exec("DELETE FROM GroupMembers WHERE UserId = :UserId", map[string]interface{}{"UserId": userId}); err != nil {
+			result.Err = model.NewAppError("SqlCustomGroupStore.PermanentDeleteMembersByUser", "store.sql_custom_group.remove_member.app_error", nil, "user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
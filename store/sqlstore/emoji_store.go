@@ -4,8 +4,10 @@
 package sqlstore
 
 import (
+	"bytes"
 	"database/sql"
 	"net/http"
+	"strconv"
 
 	"github.com/mattermost/mattermost-server/einterfaces"
 	"github.com/mattermost/mattermost-server/model"
@@ -38,6 +40,10 @@ func NewSqlEmojiStore(sqlStore SqlStore, metrics einterfaces.MetricsInterface) s
 		table.ColMap("Name").SetMaxSize(64)
 
 		table.SetUniqueTogether("Name", "DeleteAt")
+
+		tableStats := db.AddTableWithName(model.EmojiStats{}, "EmojiStats").SetKeys(false, "EmojiId")
+		tableStats.ColMap("EmojiId").SetMaxSize(26)
+		tableStats.ColMap("Name").SetMaxSize(64)
 	}
 
 	return s
@@ -128,6 +134,44 @@ func (es SqlEmojiStore) GetByName(name string) store.StoreChannel {
 	})
 }
 
+// GetMultipleByName returns the emojis with any of the given names. Names that don't belong to an emoji are
+// ignored.
+func (es SqlEmojiStore) GetMultipleByName(names []string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		emojis := []*model.Emoji{}
+
+		if len(names) == 0 {
+			result.Data = emojis
+			return
+		}
+
+		keys := bytes.Buffer{}
+		params := make(map[string]interface{}, len(names))
+		for i, name := range names {
+			if keys.Len() > 0 {
+				keys.WriteString(",")
+			}
+
+			key := "Name" + strconv.Itoa(i)
+			keys.WriteString(":" + key)
+			params[key] = name
+		}
+
+		if _, err := es.GetReplica().Select(&emojis,
+			`SELECT
+				*
+			FROM
+				Emoji
+			WHERE
+				Name IN (`+keys.String()+`)
+				AND DeleteAt = 0`, params); err != nil {
+			result.Err = model.NewAppError("SqlEmojiStore.GetMultipleByName", "store.sql_emoji.get_multiple_by_name.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = emojis
+		}
+	})
+}
+
 func (es SqlEmojiStore) GetList(offset, limit int, sort string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		var emoji []*model.Emoji
@@ -195,3 +239,107 @@ func (es SqlEmojiStore) Search(name string, prefixOnly bool, limit int) store.St
 		}
 	})
 }
+
+// RollUpStats replaces the usage stats of every custom emoji with ones counted from the reactions that use it.
+func (es SqlEmojiStore) RollUpStats(updateAt int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		transaction, err := es.GetMaster().Begin()
+		if err != nil {
+			result.Err = model.NewAppError("SqlEmojiStore.RollUpStats", "store.sql_emoji.roll_up_stats.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := transaction.Exec("DELETE FROM EmojiStats"); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlEmojiStore.RollUpStats", "store.sql_emoji.roll_up_stats.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		sqlResult, err := transaction.Exec(
+			`INSERT INTO EmojiStats
+				(EmojiId, Name, UsageCount, LastUsedAt, UpdateAt)
+			SELECT
+				Emoji.Id, Emoji.Name, COUNT(Reactions.PostId), COALESCE(MAX(Reactions.CreateAt), 0), :UpdateAt
+			FROM
+				Emoji
+			LEFT JOIN
+				Reactions ON Reactions.EmojiName = Emoji.Name
+			WHERE
+				Emoji.DeleteAt = 0
+			GROUP BY
+				Emoji.Id, Emoji.Name`, map[string]interface{}{"UpdateAt": updateAt})
+		if err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlEmojiStore.RollUpStats", "store.sql_emoji.roll_up_stats.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlEmojiStore.RollUpStats", "store.sql_emoji.roll_up_stats.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rows, _ := sqlResult.RowsAffected()
+		result.Data = rows
+	})
+}
+
+// GetStats returns the usage stats of custom emoji, most used first. Emoji created since the stats were last
+// rolled up are included as unused.
+func (es SqlEmojiStore) GetStats(offset, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var stats []*model.EmojiStats
+
+		if _, err := es.GetReplica().Select(&stats,
+			`SELECT
+				Emoji.Id AS EmojiId,
+				Emoji.Name AS Name,
+				COALESCE(EmojiStats.UsageCount, 0) AS UsageCount,
+				COALESCE(EmojiStats.LastUsedAt, 0) AS LastUsedAt,
+				COALESCE(EmojiStats.UpdateAt, 0) AS UpdateAt
+			FROM
+				Emoji
+			LEFT JOIN
+				EmojiStats ON EmojiStats.EmojiId = Emoji.Id
+			WHERE
+				Emoji.DeleteAt = 0
+			ORDER BY
+				UsageCount DESC, LastUsedAt DESC, Name ASC
+			LIMIT :Limit OFFSET :Offset`, map[string]interface{}{"Offset": offset, "Limit": limit}); err != nil {
+			result.Err = model.NewAppError("SqlEmojiStore.GetStats", "store.sql_emoji.get_stats.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = stats
+		}
+	})
+}
+
+// GetUnused returns the custom emoji which were created before the given time and which haven't been used as
+// a reaction since then. Since the stats are only rolled up periodically, this checks the reactions themselves.
+func (es SqlEmojiStore) GetUnused(before int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var emoji []*model.Emoji
+
+		if _, err := es.GetReplica().Select(&emoji,
+			`SELECT
+				*
+			FROM
+				Emoji
+			WHERE
+				DeleteAt = 0
+				AND CreateAt < :Before
+				AND NOT EXISTS (
+					SELECT
+						1
+					FROM
+						Reactions
+					WHERE
+						Reactions.EmojiName = Emoji.Name
+						AND Reactions.CreateAt >= :Before
+				)
+			ORDER BY Name`, map[string]interface{}{"Before": before}); err != nil {
+			result.Err = model.NewAppError("SqlEmojiStore.GetUnused", "store.sql_emoji.get_unused.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = emoji
+		}
+	})
+}
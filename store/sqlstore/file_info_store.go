@@ -4,8 +4,11 @@
 package sqlstore
 
 import (
+	"bytes"
 	"database/sql"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/mattermost/mattermost-server/einterfaces"
 	"github.com/mattermost/mattermost-server/model"
@@ -43,12 +46,16 @@ func NewSqlFileInfoStore(sqlStore SqlStore, metrics einterfaces.MetricsInterface
 		table.ColMap("Id").SetMaxSize(26)
 		table.ColMap("CreatorId").SetMaxSize(26)
 		table.ColMap("PostId").SetMaxSize(26)
+		table.ColMap("ChannelId").SetMaxSize(26)
 		table.ColMap("Path").SetMaxSize(512)
 		table.ColMap("ThumbnailPath").SetMaxSize(512)
 		table.ColMap("PreviewPath").SetMaxSize(512)
 		table.ColMap("Name").SetMaxSize(256)
 		table.ColMap("Extension").SetMaxSize(64)
 		table.ColMap("MimeType").SetMaxSize(256)
+		table.ColMap("ContentType").SetMaxSize(256)
+		table.ColMap("Language").SetMaxSize(32)
+		table.ColMap("Hash").SetMaxSize(64)
 	}
 
 	return s
@@ -59,6 +66,8 @@ func (fs SqlFileInfoStore) CreateIndexesIfNotExists() {
 	fs.CreateIndexIfNotExists("idx_fileinfo_create_at", "FileInfo", "CreateAt")
 	fs.CreateIndexIfNotExists("idx_fileinfo_delete_at", "FileInfo", "DeleteAt")
 	fs.CreateIndexIfNotExists("idx_fileinfo_postid_at", "FileInfo", "PostId")
+	fs.CreateIndexIfNotExists("idx_fileinfo_creator_id", "FileInfo", "CreatorId")
+	fs.CreateIndexIfNotExists("idx_fileinfo_hash", "FileInfo", "Hash")
 }
 
 func (fs SqlFileInfoStore) Save(info *model.FileInfo) store.StoreChannel {
@@ -119,6 +128,38 @@ func (fs SqlFileInfoStore) GetByPath(path string) store.StoreChannel {
 	})
 }
 
+// GetByHash returns the earliest info, including deleted ones, for a file with the given hash and size that's still in
+// the file store. If creatorId isn't empty, only files uploaded by that user are returned.
+func (fs SqlFileInfoStore) GetByHash(hash string, size int64, creatorId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		query := `
+			SELECT
+				*
+			FROM
+				FileInfo
+			WHERE
+				Hash = :Hash
+				AND Size = :Size
+				AND MissingAt = 0`
+		if creatorId != "" {
+			query += " AND CreatorId = :CreatorId"
+		}
+		query += " ORDER BY CreateAt, Id LIMIT 1"
+
+		info := &model.FileInfo{}
+		if err := fs.GetMaster().SelectOne(info, query, map[string]interface{}{"Hash": hash, "Size": size, "CreatorId": creatorId}); err != nil {
+			if err == sql.ErrNoRows {
+				result.Err = model.NewAppError("SqlFileInfoStore.GetByHash", "store.sql_file_info.get_by_hash.app_error", nil, "hash="+hash+", "+err.Error(), http.StatusNotFound)
+			} else {
+				result.Err = model.NewAppError("SqlFileInfoStore.GetByHash", "store.sql_file_info.get_by_hash.app_error", nil, "hash="+hash+", "+err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		result.Data = info
+	})
+}
+
 func (fs SqlFileInfoStore) InvalidateFileInfosForPostCache(postId string) {
 	fileInfoCache.Remove(postId)
 	if fs.metrics != nil {
@@ -126,6 +167,90 @@ func (fs SqlFileInfoStore) InvalidateFileInfosForPostCache(postId string) {
 	}
 }
 
+func (fs SqlFileInfoStore) GetForUser(userId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var infos []*model.FileInfo
+
+		if _, err := fs.GetReplica().Select(&infos,
+			`SELECT
+				*
+			FROM
+				FileInfo
+			WHERE
+				CreatorId = :CreatorId
+				AND DeleteAt = 0
+			ORDER BY
+				CreateAt`, map[string]interface{}{"CreatorId": userId}); err != nil {
+			result.Err = model.NewAppError("SqlFileInfoStore.GetForUser",
+				"store.sql_file_info.get_for_user.app_error", nil, "creator_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = infos
+		}
+	})
+}
+
+// fileInfoFilterQuery returns the FROM and WHERE clauses that select the files that haven't been deleted matching
+// the filter, along with their parameters.
+func fileInfoFilterQuery(filter *model.FileInfoFilter) (string, map[string]interface{}) {
+	from := "FileInfo"
+	where := []string{"FileInfo.CreatorId = :CreatorId", "FileInfo.DeleteAt = 0"}
+	params := map[string]interface{}{"CreatorId": filter.CreatorId}
+
+	if filter.TeamId != "" {
+		from = "FileInfo INNER JOIN Posts ON Posts.Id = FileInfo.PostId INNER JOIN Channels ON Channels.Id = Posts.ChannelId"
+		where = append(where, "Channels.TeamId = :TeamId")
+		params["TeamId"] = filter.TeamId
+	} else if filter.ChannelId != "" {
+		from = "FileInfo INNER JOIN Posts ON Posts.Id = FileInfo.PostId"
+	}
+
+	if filter.ChannelId != "" {
+		where = append(where, "Posts.ChannelId = :ChannelId")
+		params["ChannelId"] = filter.ChannelId
+	}
+
+	if filter.CreatedAfter > 0 {
+		where = append(where, "FileInfo.CreateAt >= :CreatedAfter")
+		params["CreatedAfter"] = filter.CreatedAfter
+	}
+	if filter.CreatedBefore > 0 {
+		where = append(where, "FileInfo.CreateAt < :CreatedBefore")
+		params["CreatedBefore"] = filter.CreatedBefore
+	}
+
+	return "FROM " + from + " WHERE " + strings.Join(where, " AND "), params
+}
+
+// GetWithFilter returns a page of the files matching the filter, with the newest first.
+func (fs SqlFileInfoStore) GetWithFilter(filter *model.FileInfoFilter, offset int, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		query, params := fileInfoFilterQuery(filter)
+		params["Offset"] = offset
+		params["Limit"] = limit
+
+		var infos []*model.FileInfo
+		if _, err := fs.GetReplica().Select(&infos, "SELECT FileInfo.* "+query+" ORDER BY FileInfo.CreateAt DESC, FileInfo.Id LIMIT :Limit OFFSET :Offset", params); err != nil {
+			result.Err = model.NewAppError("SqlFileInfoStore.GetWithFilter", "store.sql_file_info.get_with_filter.app_error", nil, "creator_id="+filter.CreatorId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = infos
+		}
+	})
+}
+
+// GetStatsWithFilter returns the number and total size of the files matching the filter.
+func (fs SqlFileInfoStore) GetStatsWithFilter(filter *model.FileInfoFilter) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		query, params := fileInfoFilterQuery(filter)
+
+		var stats model.FileInfoStats
+		if err := fs.GetReplica().SelectOne(&stats, "SELECT COUNT(FileInfo.Id) AS TotalCount, COALESCE(SUM(FileInfo.Size), 0) AS TotalSize "+query, params); err != nil {
+			result.Err = model.NewAppError("SqlFileInfoStore.GetStatsWithFilter", "store.sql_file_info.get_stats_with_filter.app_error", nil, "creator_id="+filter.CreatorId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = &stats
+		}
+	})
+}
+
 func (fs SqlFileInfoStore) GetForPost(postId string, readFromMaster bool, allowFromCache bool) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		if allowFromCache {
@@ -177,6 +302,47 @@ func (fs SqlFileInfoStore) GetForPost(postId string, readFromMaster bool, allowF
 	})
 }
 
+// GetForPosts returns the file infos attached to any of the given posts, skipping the cache so that a whole
+// page of posts can be loaded in one query.
+func (fs SqlFileInfoStore) GetForPosts(postIds []string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		infos := []*model.FileInfo{}
+
+		if len(postIds) == 0 {
+			result.Data = infos
+			return
+		}
+
+		keys := bytes.Buffer{}
+		params := make(map[string]interface{}, len(postIds))
+		for i, postId := range postIds {
+			if keys.Len() > 0 {
+				keys.WriteString(",")
+			}
+
+			key := "PostId" + strconv.Itoa(i)
+			keys.WriteString(":" + key)
+			params[key] = postId
+		}
+
+		if _, err := fs.GetReplica().Select(&infos,
+			`SELECT
+				*
+			FROM
+				FileInfo
+			WHERE
+				PostId IN (`+keys.String()+`)
+				AND DeleteAt = 0
+			ORDER BY
+				CreateAt`, params); err != nil {
+			result.Err = model.NewAppError("SqlFileInfoStore.GetForPosts",
+				"store.sql_file_info.get_for_posts.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = infos
+		}
+	})
+}
+
 func (fs SqlFileInfoStore) AttachToPost(fileId, postId string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		if _, err := fs.GetMaster().Exec(
@@ -246,3 +412,144 @@ func (s SqlFileInfoStore) PermanentDeleteBatch(endTime int64, limit int64) store
 		}
 	})
 }
+
+// PermanentDeleteForChannelBatch deletes up to limit of the infos, including deleted ones, for the files that were
+// uploaded to the channel or attached to its posts, returning them so that the files can be removed from the file
+// store.
+func (fs SqlFileInfoStore) PermanentDeleteForChannelBatch(channelId string, limit int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var infos []*model.FileInfo
+		if _, err := fs.GetMaster().Select(&infos, `
+			SELECT
+				*
+			FROM
+				FileInfo
+			WHERE
+				ChannelId = :ChannelId
+				OR PostId IN (SELECT Id FROM Posts WHERE ChannelId = :ChannelId)
+			LIMIT :Limit`, map[string]interface{}{"ChannelId": channelId, "Limit": limit}); err != nil {
+			result.Err = model.NewAppError("SqlFileInfoStore.PermanentDeleteForChannelBatch", "store.sql_file_info.permanent_delete_for_channel_batch.app_error", nil, "channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if len(infos) > 0 {
+			fileIds := make([]string, len(infos))
+			for i, info := range infos {
+				fileIds[i] = info.Id
+			}
+
+			props := map[string]interface{}{}
+			if _, err := fs.GetMaster().Exec("DELETE FROM FileInfo WHERE Id IN ("+buildIdListQuery("fileId", fileIds, props)+")", props); err != nil {
+				result.Err = model.NewAppError("SqlFileInfoStore.PermanentDeleteForChannelBatch", "store.sql_file_info.permanent_delete_for_channel_batch.app_error", nil, "channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		result.Data = infos
+	})
+}
+
+// GetBatchAfterId returns up to limit of the infos, including deleted ones, with ids after afterId in order of id, so
+// that all of them can be paged through by passing the id of the last one returned.
+func (fs SqlFileInfoStore) GetBatchAfterId(afterId string, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var infos []*model.FileInfo
+		if _, err := fs.GetReplica().Select(&infos, `
+			SELECT
+				*
+			FROM
+				FileInfo
+			WHERE
+				Id > :AfterId
+			ORDER BY
+				Id
+			LIMIT :Limit`, map[string]interface{}{"AfterId": afterId, "Limit": limit}); err != nil {
+			result.Err = model.NewAppError("SqlFileInfoStore.GetBatchAfterId", "store.sql_file_info.get_batch_after_id.app_error", nil, "after_id="+afterId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = infos
+	})
+}
+
+// GetReferencedPaths returns which of the paths in the file store are the path, thumbnail path or preview path of any
+// info, including deleted ones.
+func (fs SqlFileInfoStore) GetReferencedPaths(paths []string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if len(paths) == 0 {
+			result.Data = []string{}
+			return
+		}
+
+		props := map[string]interface{}{}
+		pathList := buildIdListQuery("path", paths, props)
+
+		var infos []*model.FileInfo
+		if _, err := fs.GetMaster().Select(&infos, `
+			SELECT
+				Path, ThumbnailPath, PreviewPath
+			FROM
+				FileInfo
+			WHERE
+				Path IN (`+pathList+`)
+				OR ThumbnailPath IN (`+pathList+`)
+				OR PreviewPath IN (`+pathList+`)`, props); err != nil {
+			result.Err = model.NewAppError("SqlFileInfoStore.GetReferencedPaths", "store.sql_file_info.get_referenced_paths.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		requested := make(map[string]bool, len(paths))
+		for _, path := range paths {
+			requested[path] = true
+		}
+
+		referenced := []string{}
+		for _, info := range infos {
+			for _, path := range []string{info.Path, info.ThumbnailPath, info.PreviewPath} {
+				if requested[path] {
+					referenced = append(referenced, path)
+					delete(requested, path)
+				}
+			}
+		}
+
+		result.Data = referenced
+	})
+}
+
+// SetMissingAt sets when the files were found to be missing from the file store, or clears it with 0 once they're
+// found again.
+func (fs SqlFileInfoStore) SetMissingAt(fileIds []string, missingAt int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if len(fileIds) == 0 {
+			return
+		}
+
+		props := map[string]interface{}{"MissingAt": missingAt}
+		if _, err := fs.GetMaster().Exec("UPDATE FileInfo SET MissingAt = :MissingAt WHERE Id IN ("+buildIdListQuery("fileId", fileIds, props)+")", props); err != nil {
+			result.Err = model.NewAppError("SqlFileInfoStore.SetMissingAt", "store.sql_file_info.set_missing_at.app_error", nil, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// SetContentType saves the type detected from the file's contents, for files that were uploaded before it was.
+func (fs SqlFileInfoStore) SetContentType(fileId string, contentType string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if _, err := fs.GetMaster().Exec("UPDATE FileInfo SET ContentType = :ContentType WHERE Id = :Id", map[string]interface{}{"ContentType": contentType, "Id": fileId}); err != nil {
+			result.Err = model.NewAppError("SqlFileInfoStore.SetContentType", "store.sql_file_info.set_content_type.app_error", nil, "id="+fileId+", "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// AnalyticsCount returns the number of files that haven't been deleted.
+func (fs SqlFileInfoStore) AnalyticsCount() store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		count, err := fs.GetReplica().SelectInt("SELECT COUNT(Id) FROM FileInfo WHERE DeleteAt = 0")
+		if err != nil {
+			result.Err = model.NewAppError("SqlFileInfoStore.AnalyticsCount", "store.sql_file_info.analytics_count.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = count
+	})
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+type SqlFilePublicLinkStore struct {
+	SqlStore
+}
+
+func NewSqlFilePublicLinkStore(sqlStore SqlStore) store.FilePublicLinkStore {
+	s := &SqlFilePublicLinkStore{sqlStore}
+
+	for _, db := range sqlStore.GetAllConns() {
+		table := db.AddTableWithName(model.FilePublicLink{}, "FilePublicLinks").SetKeys(false, "Id")
+		table.ColMap("Id").SetMaxSize(26)
+		table.ColMap("FileId").SetMaxSize(26)
+		table.ColMap("ChannelId").SetMaxSize(26)
+		table.ColMap("CreatorId").SetMaxSize(26)
+	}
+
+	return s
+}
+
+func (s SqlFilePublicLinkStore) CreateIndexesIfNotExists() {
+	s.CreateIndexIfNotExists("idx_file_public_links_creator_id", "FilePublicLinks", "CreatorId")
+	s.CreateIndexIfNotExists("idx_file_public_links_channel_id", "FilePublicLinks", "ChannelId")
+}
+
+func (s SqlFilePublicLinkStore) Save(link *model.FilePublicLink) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		link.PreSave()
+
+		if result.Err = link.IsValid(); result.Err != nil {
+			return
+		}
+
+		if err := s.GetMaster().Insert(link); err != nil {
+			result.Err = model.NewAppError("SqlFilePublicLinkStore.Save", "store.sql_file_public_link.save.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = link
+		}
+	})
+}
+
+func (s SqlFilePublicLinkStore) Get(linkId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		link := model.FilePublicLink{}
+
+		if err := s.GetReplica().SelectOne(&link, "SELECT * FROM FilePublicLinks WHERE Id = :Id", map[string]interface{}{"Id": linkId}); err != nil {
+			if err == sql.ErrNoRows {
+				result.Err = model.NewAppError("SqlFilePublicLinkStore.Get", "store.sql_file_public_link.get.app_error", nil, "id="+linkId+", "+err.Error(), http.StatusNotFound)
+			} else {
+				result.Err = model.NewAppError("SqlFilePublicLinkStore.Get", "store.sql_file_public_link.get.app_error", nil, "id="+linkId+", "+err.Error(), http.StatusInternalServerError)
+			}
+		} else {
+			result.Data = &link
+		}
+	})
+}
+
+// GetActiveForUser returns the links created by the user that can still be used at the given time, with the newest
+// first.
+func (s SqlFilePublicLinkStore) GetActiveForUser(userId string, now int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var links []*model.FilePublicLink
+
+		if _, err := s.GetReplica().Select(&links,
+			`SELECT * FROM FilePublicLinks
+			WHERE CreatorId = :CreatorId AND DeleteAt = 0 AND (ExpiresAt = 0 OR ExpiresAt > :Now) AND (MaxDownloads = 0 OR DownloadCount < MaxDownloads)
+			ORDER BY CreateAt DESC`, map[string]interface{}{"CreatorId": userId, "Now": now}); err != nil {
+			result.Err = model.NewAppError("SqlFilePublicLinkStore.GetActiveForUser", "store.sql_file_public_link.get_active_for_user.app_error", nil, "user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = links
+		}
+	})
+}
+
+// GetActiveForChannel returns the links to files posted in the channel that can still be used at the given time, with
+// the newest first.
+func (s SqlFilePublicLinkStore) GetActiveForChannel(channelId string, now int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var links []*model.FilePublicLink
+
+		if _, err := s.GetReplica().Select(&links,
+			`SELECT * FROM FilePublicLinks
+			WHERE ChannelId = :ChannelId AND DeleteAt = 0 AND (ExpiresAt = 0 OR ExpiresAt > :Now) AND (MaxDownloads = 0 OR DownloadCount < MaxDownloads)
+			ORDER BY CreateAt DESC`, map[string]interface{}{"ChannelId": channelId, "Now": now}); err != nil {
+			result.Err = model.NewAppError("SqlFilePublicLinkStore.GetActiveForChannel", "store.sql_file_public_link.get_active_for_channel.app_error", nil, "channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = links
+		}
+	})
+}
+
+func (s SqlFilePublicLinkStore) Revoke(linkId string, deleteAt int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if _, err := s.GetMaster().Exec("UPDATE FilePublicLinks SET DeleteAt = :DeleteAt WHERE Id = :Id AND DeleteAt = 0", map[string]interface{}{"Id": linkId, "DeleteAt": deleteAt}); err != nil {
+			result.Err = model.NewAppError("SqlFilePublicLinkStore.Revoke", "store.sql_file_public_link.revoke.app_error", nil, "id="+linkId+", "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// CountDownload uses up one of the link's downloads. It fails if the link has been revoked, has expired or has no
+// downloads left at the given time, even if that happened after it was last read.
+func (s SqlFilePublicLinkStore) CountDownload(linkId string, now int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		sqlResult, err := s.GetMaster().Exec(
+			`UPDATE FilePublicLinks SET DownloadCount = DownloadCount + 1
+			WHERE Id = :Id AND DeleteAt = 0 AND (ExpiresAt = 0 OR ExpiresAt > :Now) AND (MaxDownloads = 0 OR DownloadCount < MaxDownloads)`,
+			map[string]interface{}{"Id": linkId, "Now": now})
+		if err != nil {
+			result.Err = model.NewAppError("SqlFilePublicLinkStore.CountDownload", "store.sql_file_public_link.count_download.app_error", nil, "id="+linkId+", "+err.Error(), http.StatusInternalServerError)
+		} else if rowsAffected, err := sqlResult.RowsAffected(); err != nil {
+			result.Err = model.NewAppError("SqlFilePublicLinkStore.CountDownload", "store.sql_file_public_link.count_download.app_error", nil, "id="+linkId+", "+err.Error(), http.StatusInternalServerError)
+		} else if rowsAffected != 1 {
+			result.Err = model.NewAppError("SqlFilePublicLinkStore.CountDownload", "store.sql_file_public_link.count_download.unavailable.app_error", nil, "id="+linkId, http.StatusBadRequest)
+		}
+	})
+}
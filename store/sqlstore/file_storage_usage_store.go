@@ -0,0 +1,309 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/mattermost/gorp"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+// The bytes of the files counted towards a user, and towards a team. Files from before FileInfo had a ChannelId are
+// counted towards the team of the channel that they were posted in.
+const (
+	fileStorageUserBytesQuery = `
+		SELECT
+			COALESCE(SUM(FileInfo.Size), 0)
+		FROM
+			FileInfo
+		WHERE
+			FileInfo.CreatorId = FileStorageUsage.Id
+			AND FileInfo.DeleteAt = 0`
+
+	fileStorageTeamBytesQuery = `
+		SELECT
+			COALESCE(SUM(FileInfo.Size), 0)
+		FROM
+			FileInfo
+			LEFT JOIN Posts ON Posts.Id = FileInfo.PostId
+			INNER JOIN Channels ON Channels.Id = COALESCE(NULLIF(FileInfo.ChannelId, ''), Posts.ChannelId)
+		WHERE
+			Channels.TeamId = FileStorageUsage.Id
+			AND FileInfo.DeleteAt = 0`
+)
+
+type SqlFileStorageUsageStore struct {
+	SqlStore
+}
+
+func NewSqlFileStorageUsageStore(sqlStore SqlStore) store.FileStorageUsageStore {
+	s := &SqlFileStorageUsageStore{sqlStore}
+
+	for _, db := range sqlStore.GetAllConns() {
+		table := db.AddTableWithName(model.FileStorageUsage{}, "FileStorageUsage").SetKeys(false, "Id")
+		table.ColMap("Id").SetMaxSize(26)
+		table.ColMap("Type").SetMaxSize(8)
+	}
+
+	return s
+}
+
+func (s SqlFileStorageUsageStore) CreateIndexesIfNotExists() {
+	s.CreateIndexIfNotExists("idx_file_storage_usage_type_bytes", "FileStorageUsage", "Type, Bytes")
+}
+
+// createIfNotExists saves an empty usage for the user or team if they don't have one yet. It's done outside of any
+// transaction since another upload may save it first.
+func (s SqlFileStorageUsageStore) createIfNotExists(id string, usageType string) error {
+	count, err := s.GetMaster().SelectInt("SELECT COUNT(*) FROM FileStorageUsage WHERE Id = :Id", map[string]interface{}{"Id": id})
+	if err != nil || count > 0 {
+		return err
+	}
+
+	usage := &model.FileStorageUsage{Id: id, Type: usageType, UpdateAt: model.GetMillis()}
+	if err := s.GetMaster().Insert(usage); err != nil && !IsUniqueConstraintError(err, []string{"PRIMARY", "filestorageusage_pkey"}) {
+		return err
+	}
+
+	return nil
+}
+
+func (s SqlFileStorageUsageStore) Get(id string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		usage := model.FileStorageUsage{}
+
+		if err := s.GetReplica().SelectOne(&usage, "SELECT * FROM FileStorageUsage WHERE Id = :Id", map[string]interface{}{"Id": id}); err != nil {
+			if err == sql.ErrNoRows {
+				result.Err = model.NewAppError("SqlFileStorageUsageStore.Get", "store.sql_file_storage_usage.get.app_error", nil, "id="+id+", "+err.Error(), http.StatusNotFound)
+			} else {
+				result.Err = model.NewAppError("SqlFileStorageUsageStore.Get", "store.sql_file_storage_usage.get.app_error", nil, "id="+id+", "+err.Error(), http.StatusInternalServerError)
+			}
+		} else {
+			result.Data = &usage
+		}
+	})
+}
+
+// Reserve adds size bytes to the usage of a user and of a team, unless it would take either of them past its quota.
+// A quota of 0 is unlimited, and exempt users aren't held to either quota. The team is left out if teamId is empty.
+// The size has to be more than 0, since MySQL doesn't count rows that an update leaves unchanged.
+//
+// The quota is checked by the same update that adds to the usage, so uploads that race each other near the quota
+// can't both get through. An error with an id ending in user_quota_exceeded.app_error or
+// team_quota_exceeded.app_error is returned if the usage would go over a quota, in which case neither is changed.
+func (s SqlFileStorageUsageStore) Reserve(userId string, teamId string, size int64, userQuota int64, teamQuota int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if err := s.createIfNotExists(userId, model.FILE_STORAGE_USAGE_TYPE_USER); err != nil {
+			result.Err = model.NewAppError("SqlFileStorageUsageStore.Reserve", "store.sql_file_storage_usage.reserve.app_error", nil, "user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if teamId != "" {
+			if err := s.createIfNotExists(teamId, model.FILE_STORAGE_USAGE_TYPE_TEAM); err != nil {
+				result.Err = model.NewAppError("SqlFileStorageUsageStore.Reserve", "store.sql_file_storage_usage.reserve.app_error", nil, "team_id="+teamId+", "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		transaction, err := s.GetMaster().Begin()
+		if err != nil {
+			result.Err = model.NewAppError("SqlFileStorageUsageStore.Reserve", "store.sql_file_storage_usage.reserve.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if *result = s.reserveT(transaction, userId, teamId, size, userQuota, teamQuota); result.Err != nil {
+			transaction.Rollback()
+		} else if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlFileStorageUsageStore.Reserve", "store.sql_file_storage_usage.reserve.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+func (s SqlFileStorageUsageStore) reserveT(transaction *gorp.Transaction, userId string, teamId string, size int64, userQuota int64, teamQuota int64) store.StoreResult {
+	result := store.StoreResult{}
+
+	var exempt bool
+	if err := transaction.SelectOne(&exempt, "SELECT Exempt FROM FileStorageUsage WHERE Id = :Id", map[string]interface{}{"Id": userId}); err != nil {
+		result.Err = model.NewAppError("SqlFileStorageUsageStore.Reserve", "store.sql_file_storage_usage.reserve.app_error", nil, "user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+		return result
+	}
+
+	if exempt {
+		userQuota = 0
+		teamQuota = 0
+	}
+
+	if reserved, err := s.addBytesT(transaction, userId, model.FILE_STORAGE_USAGE_TYPE_USER, size, userQuota); err != nil {
+		result.Err = model.NewAppError("SqlFileStorageUsageStore.Reserve", "store.sql_file_storage_usage.reserve.app_error", nil, "user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+		return result
+	} else if !reserved {
+		result.Err = model.NewAppError("SqlFileStorageUsageStore.Reserve", "store.sql_file_storage_usage.reserve.user_quota_exceeded.app_error", nil, "user_id="+userId, http.StatusRequestEntityTooLarge)
+		return result
+	}
+
+	if teamId != "" {
+		if reserved, err := s.addBytesT(transaction, teamId, model.FILE_STORAGE_USAGE_TYPE_TEAM, size, teamQuota); err != nil {
+			result.Err = model.NewAppError("SqlFileStorageUsageStore.Reserve", "store.sql_file_storage_usage.reserve.app_error", nil, "team_id="+teamId+", "+err.Error(), http.StatusInternalServerError)
+			return result
+		} else if !reserved {
+			result.Err = model.NewAppError("SqlFileStorageUsageStore.Reserve", "store.sql_file_storage_usage.reserve.team_quota_exceeded.app_error", nil, "team_id="+teamId, http.StatusRequestEntityTooLarge)
+			return result
+		}
+	}
+
+	return result
+}
+
+// addBytesT adds size bytes to a usage, unless it would then be over a quota other than 0, and returns whether it was
+// updated. The usage's row stays locked until the transaction ends, so concurrent uploads wait for each other.
+func (s SqlFileStorageUsageStore) addBytesT(transaction *gorp.Transaction, id string, usageType string, size int64, quota int64) (bool, error) {
+	query := "UPDATE FileStorageUsage SET Bytes = Bytes + :Size, UpdateAt = :UpdateAt WHERE Id = :Id AND Type = :Type"
+	if quota > 0 {
+		query += " AND Bytes + :Size <= :Quota"
+	}
+
+	sqlResult, err := transaction.Exec(query, map[string]interface{}{"Id": id, "Type": usageType, "Size": size, "Quota": quota, "UpdateAt": model.GetMillis()})
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := sqlResult.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// Release takes size bytes away from the usage of a user and of a team, such as when a file is deleted. The team is
+// left out if teamId is empty.
+func (s SqlFileStorageUsageStore) Release(userId string, teamId string, size int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if _, err := s.GetMaster().Exec(
+			`UPDATE
+				FileStorageUsage
+			SET
+				Bytes = CASE WHEN Bytes > :Size THEN Bytes - :Size ELSE 0 END,
+				UpdateAt = :UpdateAt
+			WHERE
+				(Id = :UserId AND Type = :UserType)
+				OR (Id = :TeamId AND Type = :TeamType)`,
+			map[string]interface{}{
+				"UserId":   userId,
+				"UserType": model.FILE_STORAGE_USAGE_TYPE_USER,
+				"TeamId":   teamId,
+				"TeamType": model.FILE_STORAGE_USAGE_TYPE_TEAM,
+				"Size":     size,
+				"UpdateAt": model.GetMillis(),
+			}); err != nil {
+			result.Err = model.NewAppError("SqlFileStorageUsageStore.Release", "store.sql_file_storage_usage.release.app_error", nil, "user_id="+userId+", team_id="+teamId+", "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// GetTop returns the users or teams using the most storage, with the biggest first.
+func (s SqlFileStorageUsageStore) GetTop(usageType string, offset int, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var usages []*model.FileStorageUsage
+
+		if _, err := s.GetReplica().Select(&usages,
+			"SELECT * FROM FileStorageUsage WHERE Type = :Type AND Bytes > 0 ORDER BY Bytes DESC, Id LIMIT :Limit OFFSET :Offset",
+			map[string]interface{}{"Type": usageType, "Limit": limit, "Offset": offset}); err != nil {
+			result.Err = model.NewAppError("SqlFileStorageUsageStore.GetTop", "store.sql_file_storage_usage.get_top.app_error", nil, "type="+usageType+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = usages
+		}
+	})
+}
+
+// SetExempt sets whether a user can upload files past the storage quotas.
+func (s SqlFileStorageUsageStore) SetExempt(userId string, exempt bool) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if err := s.createIfNotExists(userId, model.FILE_STORAGE_USAGE_TYPE_USER); err != nil {
+			result.Err = model.NewAppError("SqlFileStorageUsageStore.SetExempt", "store.sql_file_storage_usage.set_exempt.app_error", nil, "user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := s.GetMaster().Exec("UPDATE FileStorageUsage SET Exempt = :Exempt, UpdateAt = :UpdateAt WHERE Id = :Id AND Type = :Type",
+			map[string]interface{}{"Id": userId, "Type": model.FILE_STORAGE_USAGE_TYPE_USER, "Exempt": exempt, "UpdateAt": model.GetMillis()}); err != nil {
+			result.Err = model.NewAppError("SqlFileStorageUsageStore.SetExempt", "store.sql_file_storage_usage.set_exempt.app_error", nil, "user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// Reconcile adds up the files of up to limit users or teams, ordered by id, after the one with the given id, and
+// fixes the usage of any where it's drifted. Users and teams that have files but no usage yet are given one.
+func (s SqlFileStorageUsageStore) Reconcile(usageType string, afterId string, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		ownerTable := "Users"
+		bytesQuery := "(" + fileStorageUserBytesQuery + ")"
+		if usageType == model.FILE_STORAGE_USAGE_TYPE_TEAM {
+			ownerTable = "Teams"
+			bytesQuery = "(" + fileStorageTeamBytesQuery + ")"
+		}
+
+		var ids []string
+		if _, err := s.GetMaster().Select(&ids, "SELECT Id FROM "+ownerTable+" WHERE Id > :AfterId ORDER BY Id LIMIT :Limit", map[string]interface{}{"AfterId": afterId, "Limit": limit}); err != nil {
+			result.Err = model.NewAppError("SqlFileStorageUsageStore.Reconcile", "store.sql_file_storage_usage.reconcile.app_error", nil, "type="+usageType+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if len(ids) == 0 {
+			result.Data = &model.FileStorageUsageBatch{}
+			return
+		}
+
+		for _, id := range ids {
+			if err := s.createIfNotExists(id, usageType); err != nil {
+				result.Err = model.NewAppError("SqlFileStorageUsageStore.Reconcile", "store.sql_file_storage_usage.reconcile.app_error", nil, "id="+id+", "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		props := map[string]interface{}{"Type": usageType, "UpdateAt": model.GetMillis()}
+		idQuery := ""
+
+		for index, id := range ids {
+			if len(idQuery) > 0 {
+				idQuery += ", "
+			}
+
+			props["id"+strconv.Itoa(index)] = id
+			idQuery += ":id" + strconv.Itoa(index)
+		}
+
+		query := `
+			UPDATE
+				FileStorageUsage
+			SET
+				Bytes = ` + bytesQuery + `,
+				UpdateAt = :UpdateAt
+			WHERE
+				Type = :Type
+				AND Id IN (` + idQuery + `)
+				AND Bytes != ` + bytesQuery
+
+		sqlResult, err := s.GetMaster().Exec(query, props)
+		if err != nil {
+			result.Err = model.NewAppError("SqlFileStorageUsageStore.Reconcile", "store.sql_file_storage_usage.reconcile.app_error", nil, "type="+usageType+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		corrected, err := sqlResult.RowsAffected()
+		if err != nil {
+			result.Err = model.NewAppError("SqlFileStorageUsageStore.Reconcile", "store.sql_file_storage_usage.reconcile.app_error", nil, "type="+usageType+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = &model.FileStorageUsageBatch{
+			LastId:    ids[len(ids)-1],
+			Corrected: corrected,
+		}
+	})
+}
@@ -0,0 +1,211 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"context"
+	dbsql "database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const (
+	DB_HEALTH_EVENT_UNHEALTHY = "unhealthy"
+	DB_HEALTH_EVENT_RECOVERED = "recovered"
+	DB_HEALTH_EVENT_FAILOVER  = "failover"
+	DB_HEALTH_EVENT_READ_ONLY = "read_only"
+
+	DB_HEALTH_MAX_RETRY_INTERVAL = time.Minute
+)
+
+// IsMasterHealthy returns false while the health check is unable to reach the master database.
+func (ss *SqlSupplier) IsMasterHealthy() bool {
+	return atomic.LoadInt32(&ss.masterUnhealthy) == 0
+}
+
+func (ss *SqlSupplier) startHealthCheck() {
+	if ss.settings.HealthCheckIntervalSeconds == nil || *ss.settings.HealthCheckIntervalSeconds <= 0 {
+		return
+	}
+
+	threshold := model.SQL_SETTINGS_DEFAULT_HEALTH_CHECK_FAILURE_THRESHOLD
+	if ss.settings.HealthCheckFailureThreshold != nil && *ss.settings.HealthCheckFailureThreshold > 0 {
+		threshold = *ss.settings.HealthCheckFailureThreshold
+	}
+
+	ss.healthCheckStop = make(chan struct{})
+	ss.healthCheckDone = make(chan struct{})
+
+	go ss.superviseMaster(time.Duration(*ss.settings.HealthCheckIntervalSeconds)*time.Second, threshold)
+}
+
+func (ss *SqlSupplier) stopHealthCheck() {
+	if ss.healthCheckStop == nil {
+		return
+	}
+
+	close(ss.healthCheckStop)
+	<-ss.healthCheckDone
+	ss.healthCheckStop = nil
+}
+
+// superviseMaster pings the master database every interval and marks it as unhealthy once threshold pings in a row
+// have failed, falling back to read-only mode if one of the replicas can still be reached. While it's unhealthy, it
+// tries to reconnect, backing off up to DB_HEALTH_MAX_RETRY_INTERVAL between attempts.
+func (ss *SqlSupplier) superviseMaster(interval time.Duration, threshold int) {
+	defer close(ss.healthCheckDone)
+
+	failures := 0
+	retryInterval := interval
+
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ss.healthCheckStop:
+			return
+		case <-timer.C:
+		}
+
+		if ss.IsMasterHealthy() {
+			if err := pingConnection(ss.writableMaster().Db); err != nil {
+				failures++
+				mlog.Warn(fmt.Sprintf("Failed to ping master database attempt=%v err=%v", failures, err))
+
+				if failures >= threshold {
+					ss.setMasterHealthy(false)
+					ss.fallBackToReplica()
+					retryInterval = interval
+				}
+			} else {
+				failures = 0
+			}
+
+			timer.Reset(interval)
+		} else if ss.reconnectMaster() {
+			failures = 0
+			ss.setMasterHealthy(true)
+			ss.stopFallingBackToReplica()
+			timer.Reset(interval)
+		} else {
+			retryInterval *= 2
+			if retryInterval > DB_HEALTH_MAX_RETRY_INTERVAL {
+				retryInterval = DB_HEALTH_MAX_RETRY_INTERVAL
+			}
+
+			mlog.Info(fmt.Sprintf("Retrying connection to master database in %v", retryInterval))
+			timer.Reset(retryInterval)
+		}
+	}
+}
+
+func (ss *SqlSupplier) setMasterHealthy(healthy bool) {
+	if healthy {
+		atomic.StoreInt32(&ss.masterUnhealthy, 0)
+		mlog.Info("Master database connection recovered")
+		ss.incrementHealthEvent(DB_HEALTH_EVENT_RECOVERED)
+	} else {
+		atomic.StoreInt32(&ss.masterUnhealthy, 1)
+		mlog.Error("Master database is unreachable, requests will fail until it recovers")
+		ss.incrementHealthEvent(DB_HEALTH_EVENT_UNHEALTHY)
+	}
+}
+
+func (ss *SqlSupplier) incrementHealthEvent(event string) {
+	if ss.metrics != nil {
+		ss.metrics.IncrementDatabaseHealthEvent(event)
+	}
+}
+
+// reconnectMaster returns true once the master database can be reached again, either through the existing
+// connection pool, a new connection to DataSource, or by failing over to DataSourceFailover.
+func (ss *SqlSupplier) reconnectMaster() bool {
+	if err := pingConnection(ss.writableMaster().Db); err == nil {
+		return true
+	}
+
+	db, err := openConnection(*ss.settings.DriverName, *ss.settings.DataSource, ss.settings)
+	if err == nil {
+		ss.replaceMaster(db, *ss.settings.DataSource)
+		return true
+	}
+	mlog.Warn(fmt.Sprintf("Failed to reconnect to master database err=%v", err))
+
+	if ss.settings.DataSourceFailover == nil || *ss.settings.DataSourceFailover == "" {
+		return false
+	}
+
+	failover, err := openConnection(*ss.settings.DriverName, *ss.settings.DataSourceFailover, ss.settings)
+	if err != nil {
+		mlog.Warn(fmt.Sprintf("Failed to connect to failover database err=%v", err))
+		return false
+	}
+
+	ss.replaceMaster(failover, *ss.settings.DataSourceFailover)
+	mlog.Warn("Failed over to the standby master database")
+	ss.incrementHealthEvent(DB_HEALTH_EVENT_FAILOVER)
+
+	return true
+}
+
+// replaceMaster swaps the connection pool used by the master database, keeping its table mappings.
+func (ss *SqlSupplier) replaceMaster(db *dbsql.DB, dataSource string) {
+	ss.masterLock.Lock()
+	old := ss.master
+	master := *old
+	master.Db = db
+	ss.master = &master
+	ss.masterDataSource = dataSource
+	oldReadOnly := ss.updateReadOnlyMaster()
+	ss.masterLock.Unlock()
+
+	old.Db.Close()
+	closeConnection(oldReadOnly)
+}
+
+func openConnection(driverName, dataSource string, settings *model.SqlSettings) (*dbsql.DB, error) {
+	db, err := dbsql.Open(driverName, dataSource)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pingConnection(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	configureConnection(db, settings)
+
+	return db, nil
+}
+
+func pingConnection(db *dbsql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), DB_PING_TIMEOUT_SECS*time.Second)
+	defer cancel()
+
+	return db.PingContext(ctx)
+}
+
+// CheckConnection connects to the master database without setting up a store, and returns the version of its schema,
+// which is empty for a database that Mattermost hasn't been installed into yet.
+func CheckConnection(settings *model.SqlSettings) (string, error) {
+	db, err := openConnection(*settings.DriverName, *settings.DataSource, settings)
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+
+	var version string
+	if err := db.QueryRow("SELECT Value FROM Systems WHERE Name='Version'").Scan(&version); err != nil {
+		// The Systems table is missing or empty for a new database
+		return "", nil
+	}
+
+	return version, nil
+}
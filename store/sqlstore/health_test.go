@@ -0,0 +1,173 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// toggleProxy forwards connections to a database until it's taken down, at which point it drops all of its open
+// connections and refuses new ones.
+type toggleProxy struct {
+	listener net.Listener
+	target   string
+
+	lock  sync.Mutex
+	down  bool
+	conns map[net.Conn]bool
+}
+
+func newToggleProxy(t *testing.T, target string) *toggleProxy {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+
+	proxy := &toggleProxy{
+		listener: listener,
+		target:   target,
+		conns:    map[net.Conn]bool{},
+	}
+	go proxy.serve()
+
+	return proxy
+}
+
+func (p *toggleProxy) Addr() string {
+	return p.listener.Addr().String()
+}
+
+func (p *toggleProxy) serve() {
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		p.lock.Lock()
+		down := p.down
+		p.lock.Unlock()
+
+		if down {
+			conn.Close()
+			continue
+		}
+
+		upstream, err := net.Dial("tcp", p.target)
+		if err != nil {
+			conn.Close()
+			continue
+		}
+
+		p.lock.Lock()
+		p.conns[conn] = true
+		p.conns[upstream] = true
+		p.lock.Unlock()
+
+		go p.pipe(conn, upstream)
+		go p.pipe(upstream, conn)
+	}
+}
+
+func (p *toggleProxy) pipe(dst, src net.Conn) {
+	io.Copy(dst, src)
+	dst.Close()
+	src.Close()
+}
+
+func (p *toggleProxy) SetDown(down bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.down = down
+	if down {
+		for conn := range p.conns {
+			conn.Close()
+		}
+		p.conns = map[net.Conn]bool{}
+	}
+}
+
+func (p *toggleProxy) Close() {
+	p.listener.Close()
+	p.SetDown(true)
+}
+
+var databaseAddressPattern = regexp.MustCompile(`127\.0\.0\.1:\d+`)
+
+func newProxiedSupplier(t *testing.T, settings *model.SqlSettings, proxy *toggleProxy, failover string) *SqlSupplier {
+	proxied := *settings
+	proxied.DataSource = model.NewString(strings.Replace(*settings.DataSource, proxy.target, proxy.Addr(), 1))
+	proxied.DataSourceFailover = model.NewString(failover)
+	proxied.HealthCheckIntervalSeconds = model.NewInt(1)
+	proxied.HealthCheckFailureThreshold = model.NewInt(1)
+
+	return NewSqlSupplier(proxied, nil, nil)
+}
+
+func waitForHealth(t *testing.T, supplier *SqlSupplier, healthy bool) {
+	deadline := time.Now().Add(30 * time.Second)
+	for supplier.IsMasterHealthy() != healthy {
+		if time.Now().After(deadline) {
+			require.FailNow(t, "timed out waiting for the master database health to change", "healthy=%v", healthy)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestMasterHealthCheck(t *testing.T) {
+	for _, st := range storeTypes {
+		st := st
+		t.Run(st.Name, func(t *testing.T) {
+			target := databaseAddressPattern.FindString(*st.Settings.DataSource)
+			require.NotEmpty(t, target)
+
+			t.Run("Recover", func(t *testing.T) {
+				proxy := newToggleProxy(t, target)
+				defer proxy.Close()
+
+				supplier := newProxiedSupplier(t, st.Settings, proxy, "")
+				defer supplier.Close()
+				assert.True(t, supplier.IsMasterHealthy())
+
+				proxy.SetDown(true)
+				waitForHealth(t, supplier, false)
+
+				proxy.SetDown(false)
+				waitForHealth(t, supplier, true)
+
+				value, err := supplier.GetMaster().SelectInt("SELECT 1")
+				require.Nil(t, err)
+				assert.Equal(t, int64(1), value)
+			})
+
+			t.Run("Failover", func(t *testing.T) {
+				proxy := newToggleProxy(t, target)
+				defer proxy.Close()
+
+				supplier := newProxiedSupplier(t, st.Settings, proxy, *st.Settings.DataSource)
+				defer supplier.Close()
+
+				proxy.SetDown(true)
+				waitForHealth(t, supplier, false)
+
+				// The standby is still reachable, so the supplier recovers while the master remains down
+				waitForHealth(t, supplier, true)
+
+				value, err := supplier.GetMaster().SelectInt("SELECT 1")
+				require.Nil(t, err)
+				assert.Equal(t, int64(1), value)
+			})
+		})
+	}
+}
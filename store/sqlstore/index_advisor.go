@@ -0,0 +1,339 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// indexAdvisorQuery is one of the queries that the server runs most often, along with the index that it needs so as
+// not to slow down as its table grows.
+type indexAdvisorQuery struct {
+	name  string
+	index *schemaIndex
+
+	// query is run with the id and category placeholders replaced by literals, since EXPLAIN can't be given
+	// parameters on every database.
+	query string
+}
+
+// indexAdvisorQueries are the queries that are checked by AnalyzeIndexes. Their indexes use the same names as the
+// stores so that creating one that's suggested doesn't leave a duplicate once the server next starts.
+var indexAdvisorQueries = []*indexAdvisorQuery{
+	{
+		name:  "posts_by_channel",
+		query: "SELECT * FROM Posts WHERE ChannelId = {id} AND DeleteAt = 0 ORDER BY CreateAt DESC LIMIT 60",
+		index: &schemaIndex{name: "idx_posts_channel_id_delete_at_create_at", table: "Posts", columns: []string{"ChannelId", "DeleteAt", "CreateAt"}},
+	},
+	{
+		name:  "channel_members_by_user",
+		query: "SELECT * FROM ChannelMembers WHERE UserId = {id}",
+		index: &schemaIndex{name: "idx_channelmembers_user_id", table: "ChannelMembers", columns: []string{"UserId"}},
+	},
+	{
+		name:  "preferences_by_user_and_category",
+		query: "SELECT * FROM Preferences WHERE UserId = {id} AND Category = {category}",
+		index: &schemaIndex{name: "idx_preferences_user_id", table: "Preferences", columns: []string{"UserId"}},
+	},
+	{
+		name:  "file_infos_by_post",
+		query: "SELECT * FROM FileInfo WHERE PostId = {id} AND DeleteAt = 0",
+		index: &schemaIndex{name: "idx_fileinfo_postid_at", table: "FileInfo", columns: []string{"PostId"}},
+	},
+}
+
+// IndexAdvice is what AnalyzeIndexes found out about how the database runs one of the server's hot queries.
+type IndexAdvice struct {
+	Query string `json:"query"`
+	Table string `json:"table"`
+
+	// FullScan is true if the database reads the whole table, or the whole of an index, to run the query.
+	FullScan bool `json:"full_scan"`
+
+	// Filesort is true if the database sorts the rows itself instead of reading them in order from an index.
+	Filesort bool `json:"filesort"`
+
+	// EstimatedRows is how many rows the database expects to read from the table.
+	EstimatedRows int64 `json:"estimated_rows"`
+
+	// UsedIndex is the index that the database would read the table with, if any.
+	UsedIndex string `json:"used_index"`
+
+	// MissingIndex is true if no index fits the query, in which case Suggestion creates one.
+	MissingIndex bool   `json:"missing_index"`
+	Suggestion   string `json:"suggestion"`
+}
+
+func (a *IndexAdvice) String() string {
+	var problems []string
+	if a.FullScan {
+		problems = append(problems, "full scan")
+	}
+	if a.Filesort {
+		problems = append(problems, "filesort")
+	}
+	if len(problems) == 0 {
+		problems = append(problems, "ok")
+	}
+
+	usedIndex := "no index"
+	if a.UsedIndex != "" {
+		usedIndex = "index " + a.UsedIndex
+	}
+
+	description := fmt.Sprintf("%v on %v: %v, about %v rows using %v", a.Query, a.Table, strings.Join(problems, ", "), a.EstimatedRows, usedIndex)
+	if a.MissingIndex {
+		description += "\n  Suggested index: " + a.Suggestion
+	} else if a.FullScan || a.Filesort {
+		description += "\n  An index that fits the query exists, so the database likely chose not to use it because the table is small"
+	}
+
+	return description
+}
+
+// indexPlan is what's read from the plan of a query for the table that it's run on.
+type indexPlan struct {
+	fullScan      bool
+	filesort      bool
+	estimatedRows int64
+	usedIndex     string
+}
+
+// AnalyzeIndexes explains each of the server's hot queries to find out whether the database can run them without
+// reading whole tables or sorting rows itself, and suggests the indexes that they're missing.
+func (ss *SqlSupplier) AnalyzeIndexes() ([]*IndexAdvice, error) {
+	advice := make([]*IndexAdvice, 0, len(indexAdvisorQueries))
+	for _, query := range indexAdvisorQueries {
+		plan, err := ss.explainQuery(query)
+		if err != nil {
+			return nil, err
+		}
+
+		indexes, err := ss.tableIndexColumns(query.index.table)
+		if err != nil {
+			return nil, err
+		}
+
+		missing := !hasIndexWithPrefix(indexes, query.index.columns)
+
+		suggestion := ""
+		if missing {
+			suggestion = ss.createIndexQuery(query.index, true) + ";"
+		}
+
+		advice = append(advice, &IndexAdvice{
+			Query:         query.name,
+			Table:         query.index.table,
+			FullScan:      plan.fullScan,
+			Filesort:      plan.filesort,
+			EstimatedRows: plan.estimatedRows,
+			UsedIndex:     plan.usedIndex,
+			MissingIndex:  missing,
+			Suggestion:    suggestion,
+		})
+	}
+
+	return advice, nil
+}
+
+func (ss *SqlSupplier) explainQuery(query *indexAdvisorQuery) (*indexPlan, error) {
+	statement := strings.NewReplacer(
+		"{id}", "'"+model.NewId()+"'",
+		"{category}", "'"+model.PREFERENCE_CATEGORY_DISPLAY_SETTINGS+"'",
+	).Replace(query.query)
+
+	if ss.DriverName() == model.DATABASE_DRIVER_POSTGRES {
+		data, err := ss.GetMaster().SelectStr("EXPLAIN (FORMAT JSON) " + statement)
+		if err != nil {
+			return nil, err
+		}
+
+		return parsePostgresExplain([]byte(data), query.index.table)
+	}
+
+	rows, err := ss.GetMaster().Db.Query("EXPLAIN " + statement)
+	if err != nil {
+		return nil, err
+	}
+
+	explained, err := readExplainRows(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseMysqlExplain(explained, query.index.table), nil
+}
+
+// readExplainRows reads the rows of a MySQL EXPLAIN by the names of their columns, since which columns there are
+// depends on the version of MySQL.
+func readExplainRows(rows *sql.Rows) ([]map[string]string, error) {
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var explained []map[string]string
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]string, len(columns))
+		for i, column := range columns {
+			row[strings.ToLower(column)] = values[i].String
+		}
+		explained = append(explained, row)
+	}
+
+	return explained, rows.Err()
+}
+
+// parseMysqlExplain reads the plan of a query for a table from the rows of a traditional MySQL EXPLAIN.
+func parseMysqlExplain(rows []map[string]string, table string) *indexPlan {
+	plan := &indexPlan{}
+	for _, row := range rows {
+		if !strings.EqualFold(row["table"], table) {
+			continue
+		}
+
+		// ALL reads every row of the table and index reads every entry of an index
+		plan.fullScan = row["type"] == "ALL" || row["type"] == "index"
+		plan.filesort = strings.Contains(row["extra"], "Using filesort")
+		plan.usedIndex = row["key"]
+		plan.estimatedRows, _ = strconv.ParseInt(row["rows"], 10, 64)
+	}
+
+	return plan
+}
+
+type postgresPlanNode struct {
+	NodeType     string              `json:"Node Type"`
+	RelationName string              `json:"Relation Name"`
+	IndexName    string              `json:"Index Name"`
+	PlanRows     float64             `json:"Plan Rows"`
+	Plans        []*postgresPlanNode `json:"Plans"`
+}
+
+// parsePostgresExplain reads the plan of a query for a table from the output of EXPLAIN (FORMAT JSON).
+func parsePostgresExplain(data []byte, table string) (*indexPlan, error) {
+	var explained []struct {
+		Plan *postgresPlanNode `json:"Plan"`
+	}
+	if err := json.Unmarshal(data, &explained); err != nil {
+		return nil, err
+	}
+
+	plan := &indexPlan{}
+
+	var walk func(node *postgresPlanNode)
+	walk = func(node *postgresPlanNode) {
+		if node.NodeType == "Sort" || node.NodeType == "Incremental Sort" {
+			plan.filesort = true
+		}
+
+		if strings.EqualFold(node.RelationName, table) {
+			plan.fullScan = node.NodeType == "Seq Scan"
+			plan.estimatedRows = int64(node.PlanRows)
+			if node.IndexName != "" {
+				plan.usedIndex = node.IndexName
+			}
+		}
+
+		// Bitmap scans name the table and the index in separate nodes
+		if node.NodeType == "Bitmap Index Scan" {
+			plan.usedIndex = node.IndexName
+		}
+
+		for _, child := range node.Plans {
+			walk(child)
+		}
+	}
+
+	for _, statement := range explained {
+		if statement.Plan != nil {
+			walk(statement.Plan)
+		}
+	}
+
+	return plan, nil
+}
+
+// tableIndexColumns returns the columns of each of a table's indexes, in order. Columns of indexes on expressions
+// are returned as empty strings.
+func (ss *SqlSupplier) tableIndexColumns(table string) (map[string][]string, error) {
+	var columns []struct {
+		IndexName  string
+		ColumnName string
+	}
+
+	if ss.DriverName() == model.DATABASE_DRIVER_POSTGRES {
+		if _, err := ss.GetMaster().Select(&columns, `
+			SELECT
+				i.relname AS IndexName,
+				COALESCE(a.attname, '') AS ColumnName
+			FROM
+				pg_index x
+				JOIN pg_class i ON i.oid = x.indexrelid
+				JOIN pg_class t ON t.oid = x.indrelid
+				JOIN pg_namespace n ON n.oid = t.relnamespace
+				CROSS JOIN LATERAL unnest(x.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord)
+				LEFT JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+			WHERE
+				n.nspname = current_schema()
+				AND t.relname = :Table
+			ORDER BY
+				i.relname, k.ord`, map[string]interface{}{"Table": strings.ToLower(table)}); err != nil {
+			return nil, err
+		}
+	} else {
+		if _, err := ss.GetMaster().Select(&columns, "SELECT INDEX_NAME AS IndexName, COALESCE(COLUMN_NAME, '') AS ColumnName FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = :Table ORDER BY INDEX_NAME, SEQ_IN_INDEX", map[string]interface{}{"Table": table}); err != nil {
+			return nil, err
+		}
+	}
+
+	indexes := map[string][]string{}
+	for _, column := range columns {
+		indexes[column.IndexName] = append(indexes[column.IndexName], column.ColumnName)
+	}
+
+	return indexes, nil
+}
+
+// hasIndexWithPrefix returns true if one of the indexes starts with the given columns, in which case it can be used
+// by a query in place of an index on only those columns.
+func hasIndexWithPrefix(indexes map[string][]string, columns []string) bool {
+	for _, indexColumns := range indexes {
+		if len(indexColumns) < len(columns) {
+			continue
+		}
+
+		matches := true
+		for i, column := range columns {
+			if !strings.EqualFold(indexColumns[i], column) {
+				matches = false
+				break
+			}
+		}
+
+		if matches {
+			return true
+		}
+	}
+
+	return false
+}
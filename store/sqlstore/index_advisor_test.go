@@ -0,0 +1,96 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeIndexes(t *testing.T) {
+	findAdvice := func(advice []*IndexAdvice, query string) *IndexAdvice {
+		for _, queryAdvice := range advice {
+			if queryAdvice.Query == query {
+				return queryAdvice
+			}
+		}
+		return nil
+	}
+
+	for _, st := range storeTypes {
+		st := st
+		t.Run(st.Name, func(t *testing.T) {
+			supplier := NewSqlSupplierForSchemaCheck(*st.Settings)
+			defer supplier.Close()
+
+			advice, err := supplier.AnalyzeIndexes()
+			require.Nil(t, err)
+			require.Len(t, advice, len(indexAdvisorQueries))
+			for _, queryAdvice := range advice {
+				assert.False(t, queryAdvice.MissingIndex, queryAdvice.String())
+				assert.Empty(t, queryAdvice.Suggestion)
+			}
+
+			supplier.RemoveIndexIfExists("idx_channelmembers_user_id", "ChannelMembers")
+			defer supplier.CreateIndexIfNotExists("idx_channelmembers_user_id", "ChannelMembers", "UserId")
+
+			advice, err = supplier.AnalyzeIndexes()
+			require.Nil(t, err)
+
+			missing := findAdvice(advice, "channel_members_by_user")
+			require.NotNil(t, missing)
+			assert.True(t, missing.MissingIndex)
+			assert.True(t, missing.FullScan, "the members should be read in full without the index")
+			assert.Contains(t, missing.Suggestion, "idx_channelmembers_user_id ON ChannelMembers (UserId)")
+			assert.Contains(t, missing.String(), missing.Suggestion)
+
+			assert.False(t, findAdvice(advice, "posts_by_channel").MissingIndex)
+		})
+	}
+}
+
+func TestParseMysqlExplain(t *testing.T) {
+	plan := parseMysqlExplain([]map[string]string{
+		{"table": "Posts", "type": "ref", "key": "idx_posts_channel_id", "rows": "120", "extra": "Using where; Using filesort"},
+	}, "Posts")
+	assert.False(t, plan.fullScan)
+	assert.True(t, plan.filesort)
+	assert.Equal(t, "idx_posts_channel_id", plan.usedIndex)
+	assert.Equal(t, int64(120), plan.estimatedRows)
+
+	plan = parseMysqlExplain([]map[string]string{
+		{"table": "ChannelMembers", "type": "ALL", "key": "", "rows": "5000", "extra": "Using where"},
+	}, "ChannelMembers")
+	assert.True(t, plan.fullScan)
+	assert.False(t, plan.filesort)
+	assert.Equal(t, "", plan.usedIndex)
+	assert.Equal(t, int64(5000), plan.estimatedRows)
+}
+
+func TestParsePostgresExplain(t *testing.T) {
+	plan, err := parsePostgresExplain([]byte(`[{"Plan": {"Node Type": "Limit", "Plan Rows": 60, "Plans": [
+		{"Node Type": "Sort", "Plan Rows": 80, "Plans": [
+			{"Node Type": "Seq Scan", "Relation Name": "posts", "Plan Rows": 80}
+		]}
+	]}}]`), "Posts")
+	require.Nil(t, err)
+	assert.True(t, plan.fullScan)
+	assert.True(t, plan.filesort)
+	assert.Equal(t, "", plan.usedIndex)
+	assert.Equal(t, int64(80), plan.estimatedRows)
+
+	plan, err = parsePostgresExplain([]byte(`[{"Plan": {"Node Type": "Bitmap Heap Scan", "Relation Name": "channelmembers", "Plan Rows": 12, "Plans": [
+		{"Node Type": "Bitmap Index Scan", "Index Name": "idx_channelmembers_user_id", "Plan Rows": 12}
+	]}}]`), "ChannelMembers")
+	require.Nil(t, err)
+	assert.False(t, plan.fullScan)
+	assert.False(t, plan.filesort)
+	assert.Equal(t, "idx_channelmembers_user_id", plan.usedIndex)
+	assert.Equal(t, int64(12), plan.estimatedRows)
+
+	_, err = parsePostgresExplain([]byte("not json"), "Posts")
+	assert.NotNil(t, err)
+}
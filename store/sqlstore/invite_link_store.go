@@ -0,0 +1,149 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+type SqlInviteLinkStore struct {
+	SqlStore
+}
+
+func NewSqlInviteLinkStore(sqlStore SqlStore) store.InviteLinkStore {
+	s := &SqlInviteLinkStore{sqlStore}
+
+	for _, db := range sqlStore.GetAllConns() {
+		table := db.AddTableWithName(model.InviteLink{}, "InviteLinks").SetKeys(false, "Id")
+		table.ColMap("Id").SetMaxSize(26)
+		table.ColMap("TeamId").SetMaxSize(26)
+		table.ColMap("CreatorId").SetMaxSize(26)
+
+		redemptions := db.AddTableWithName(model.InviteLinkRedemption{}, "InviteLinkRedemptions").SetKeys(false, "LinkId", "UserId")
+		redemptions.ColMap("LinkId").SetMaxSize(26)
+		redemptions.ColMap("UserId").SetMaxSize(26)
+	}
+
+	return s
+}
+
+func (s SqlInviteLinkStore) CreateIndexesIfNotExists() {
+	s.CreateIndexIfNotExists("idx_invite_links_team_id", "InviteLinks", "TeamId")
+	s.CreateIndexIfNotExists("idx_invite_link_redemptions_user_id", "InviteLinkRedemptions", "UserId")
+}
+
+func (s SqlInviteLinkStore) Save(link *model.InviteLink) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		link.PreSave()
+
+		if result.Err = link.IsValid(); result.Err != nil {
+			return
+		}
+
+		if err := s.GetMaster().Insert(link); err != nil {
+			result.Err = model.NewAppError("SqlInviteLinkStore.Save", "store.sql_invite_link.save.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = link
+		}
+	})
+}
+
+func (s SqlInviteLinkStore) Get(linkId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		link := model.InviteLink{}
+
+		if err := s.GetReplica().SelectOne(&link, "SELECT * FROM InviteLinks WHERE Id = :Id", map[string]interface{}{"Id": linkId}); err != nil {
+			if err == sql.ErrNoRows {
+				result.Err = model.NewAppError("SqlInviteLinkStore.Get", "store.sql_invite_link.get.app_error", nil, "id="+linkId+", "+err.Error(), http.StatusNotFound)
+			} else {
+				result.Err = model.NewAppError("SqlInviteLinkStore.Get", "store.sql_invite_link.get.app_error", nil, "id="+linkId+", "+err.Error(), http.StatusInternalServerError)
+			}
+		} else {
+			result.Data = &link
+		}
+	})
+}
+
+// GetForTeam returns all of the team's links, including ones that have been revoked, with the newest first.
+func (s SqlInviteLinkStore) GetForTeam(teamId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var links []*model.InviteLink
+
+		if _, err := s.GetReplica().Select(&links, "SELECT * FROM InviteLinks WHERE TeamId = :TeamId ORDER BY CreateAt DESC", map[string]interface{}{"TeamId": teamId}); err != nil {
+			result.Err = model.NewAppError("SqlInviteLinkStore.GetForTeam", "store.sql_invite_link.get_for_team.app_error", nil, "team_id="+teamId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = links
+		}
+	})
+}
+
+func (s SqlInviteLinkStore) Revoke(linkId string, deleteAt int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if _, err := s.GetMaster().Exec("UPDATE InviteLinks SET DeleteAt = :DeleteAt WHERE Id = :Id AND DeleteAt = 0", map[string]interface{}{"Id": linkId, "DeleteAt": deleteAt}); err != nil {
+			result.Err = model.NewAppError("SqlInviteLinkStore.Revoke", "store.sql_invite_link.revoke.app_error", nil, "id="+linkId+", "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// Redeem uses up one of the link's uses and records that the user redeemed it. It fails if the link has been
+// revoked, has expired or has no uses left at the given time, even if that happened after it was last read, and
+// if the user has already redeemed it.
+func (s SqlInviteLinkStore) Redeem(linkId string, userId string, now int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		transaction, err := s.GetMaster().Begin()
+		if err != nil {
+			result.Err = model.NewAppError("SqlInviteLinkStore.Redeem", "store.sql_invite_link.redeem.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		redemption := &model.InviteLinkRedemption{LinkId: linkId, UserId: userId, CreateAt: now}
+
+		sqlResult, err := transaction.Exec(
+			`UPDATE InviteLinks SET Uses = Uses + 1
+			WHERE Id = :Id AND DeleteAt = 0 AND (ExpiresAt = 0 OR ExpiresAt > :Now) AND (MaxUses = 0 OR Uses < MaxUses)`,
+			map[string]interface{}{"Id": linkId, "Now": now})
+		if err != nil {
+			result.Err = model.NewAppError("SqlInviteLinkStore.Redeem", "store.sql_invite_link.redeem.app_error", nil, "id="+linkId+", "+err.Error(), http.StatusInternalServerError)
+		} else if rowsAffected, err := sqlResult.RowsAffected(); err != nil {
+			result.Err = model.NewAppError("SqlInviteLinkStore.Redeem", "store.sql_invite_link.redeem.app_error", nil, "id="+linkId+", "+err.Error(), http.StatusInternalServerError)
+		} else if rowsAffected != 1 {
+			result.Err = model.NewAppError("SqlInviteLinkStore.Redeem", "store.sql_invite_link.redeem.unavailable.app_error", nil, "id="+linkId, http.StatusBadRequest)
+		} else if err := transaction.Insert(redemption); err != nil {
+			if IsUniqueConstraintError(err, []string{"PRIMARY", "invitelinkredemptions_pkey"}) {
+				result.Err = model.NewAppError("SqlInviteLinkStore.Redeem", "store.sql_invite_link.redeem.exists.app_error", nil, "id="+linkId+", user_id="+userId, http.StatusBadRequest)
+			} else {
+				result.Err = model.NewAppError("SqlInviteLinkStore.Redeem", "store.sql_invite_link.redeem.app_error", nil, "id="+linkId+", "+err.Error(), http.StatusInternalServerError)
+			}
+		}
+
+		if result.Err == nil {
+			if err := transaction.Commit(); err != nil {
+				// don't need to rollback here since the transaction is already closed
+				result.Err = model.NewAppError("SqlInviteLinkStore.Redeem", "store.sql_invite_link.redeem.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			} else {
+				result.Data = redemption
+			}
+		} else {
+			if err := transaction.Rollback(); err != nil {
+				result.Err = model.NewAppError("SqlInviteLinkStore.Redeem", "store.sql_invite_link.redeem.rollback_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			}
+		}
+	})
+}
+
+func (s SqlInviteLinkStore) GetRedemptions(linkId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var redemptions []*model.InviteLinkRedemption
+
+		if _, err := s.GetReplica().Select(&redemptions, "SELECT * FROM InviteLinkRedemptions WHERE LinkId = :LinkId ORDER BY CreateAt", map[string]interface{}{"LinkId": linkId}); err != nil {
+			result.Err = model.NewAppError("SqlInviteLinkStore.GetRedemptions", "store.sql_invite_link.get_redemptions.app_error", nil, "id="+linkId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = redemptions
+		}
+	})
+}
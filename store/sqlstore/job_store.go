@@ -32,6 +32,7 @@ func NewSqlJobStore(sqlStore SqlStore) store.JobStore {
 
 func (jss SqlJobStore) CreateIndexesIfNotExists() {
 	jss.CreateIndexIfNotExists("idx_jobs_type", "Jobs", "Type")
+	jss.CreateIndexIfNotExists("idx_jobs_create_at", "Jobs", "CreateAt")
 }
 
 func (jss SqlJobStore) Save(job *model.Job) store.StoreChannel {
@@ -298,3 +299,58 @@ func (jss SqlJobStore) Delete(id string) store.StoreChannel {
 		}
 	})
 }
+
+// finishedJobsCondition matches the jobs that are no longer waiting to run or running, which are the only ones that
+// are trimmed.
+const finishedJobsCondition = "Status NOT IN ('" + model.JOB_STATUS_PENDING + "', '" + model.JOB_STATUS_IN_PROGRESS + "', '" + model.JOB_STATUS_CANCEL_REQUESTED + "')"
+
+// PermanentDeleteFinishedBatch deletes up to limit jobs that were created before the given time and have finished,
+// returning how many were deleted. The progress and data of a job are stored with it, so nothing is left behind.
+func (jss SqlJobStore) PermanentDeleteFinishedBatch(endTime int64, limit int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var query string
+		if jss.DriverName() == model.DATABASE_DRIVER_POSTGRES {
+			query = "DELETE FROM Jobs WHERE Id = any (array (SELECT Id FROM Jobs WHERE CreateAt < :EndTime AND " + finishedJobsCondition + " LIMIT :Limit))"
+		} else {
+			query = "DELETE FROM Jobs WHERE CreateAt < :EndTime AND " + finishedJobsCondition + " LIMIT :Limit"
+		}
+
+		sqlResult, err := jss.GetMaster().Exec(query, map[string]interface{}{"EndTime": endTime, "Limit": limit})
+		if err != nil {
+			result.Err = model.NewAppError("SqlJobStore.PermanentDeleteFinishedBatch", "store.sql_job.permanent_delete_finished_batch.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rowsAffected, err := sqlResult.RowsAffected()
+		if err != nil {
+			result.Err = model.NewAppError("SqlJobStore.PermanentDeleteFinishedBatch", "store.sql_job.permanent_delete_finished_batch.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = rowsAffected
+	})
+}
+
+// GetFinishedCountBefore returns how many jobs were created before the given time and have finished.
+func (jss SqlJobStore) GetFinishedCountBefore(endTime int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if count, err := jss.GetReplica().SelectInt("SELECT COUNT(*) FROM Jobs WHERE CreateAt < :EndTime AND "+finishedJobsCondition, map[string]interface{}{"EndTime": endTime}); err != nil {
+			result.Err = model.NewAppError("SqlJobStore.GetFinishedCountBefore", "store.sql_job.get_finished_count_before.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = count
+		}
+	})
+}
+
+// GetFinishedMaxRowsCutoff returns the time that the oldest of the newest maxRows finished jobs was created at, so
+// that deleting the finished jobs created before it keeps at least that many. It returns 0 if there aren't more than
+// maxRows finished jobs.
+func (jss SqlJobStore) GetFinishedMaxRowsCutoff(maxRows int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if cutoff, err := jss.GetReplica().SelectInt("SELECT CreateAt FROM Jobs WHERE "+finishedJobsCondition+" ORDER BY CreateAt DESC LIMIT 1 OFFSET :Offset", map[string]interface{}{"Offset": maxRows - 1}); err != nil {
+			result.Err = model.NewAppError("SqlJobStore.GetFinishedMaxRowsCutoff", "store.sql_job.get_finished_max_rows_cutoff.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = cutoff
+		}
+	})
+}
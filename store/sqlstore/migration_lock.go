@@ -0,0 +1,139 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const (
+	MIGRATION_LOCK_NAME = "MigrationLock"
+
+	// The lock is refreshed while the migrations run, so one that hasn't been refreshed for this long was left behind
+	// by a server that stopped part way through migrating.
+	MIGRATION_LOCK_EXPIRY_MILLISECONDS = 5 * 60 * 1000
+)
+
+var (
+	MIGRATION_LOCK_REFRESH_INTERVAL = time.Minute
+	MIGRATION_LOCK_POLL_INTERVAL    = time.Second
+)
+
+// migrationLock is held in the Systems table by the server that's migrating the database. Its value is unique to the
+// server holding it and changes each time that it's refreshed, so that it's only ever updated or released by that
+// server.
+type migrationLock struct {
+	ss         *SqlSupplier
+	id         string
+	value      string
+	stop       chan struct{}
+	refreshing sync.WaitGroup
+}
+
+func (l *migrationLock) nextValue() string {
+	return l.id + ":" + strconv.FormatInt(model.GetMillis(), 10)
+}
+
+func migrationLockRefreshedAt(value string) int64 {
+	refreshedAt, _ := strconv.ParseInt(value[strings.LastIndex(value, ":")+1:], 10, 64)
+	return refreshedAt
+}
+
+// LockMigrations waits until no other server is migrating the database and then takes the lock that stops any others
+// from starting to, returning the function that releases it. The Systems table is created first if it doesn't exist
+// yet, since the lock is kept in it.
+func (ss *SqlSupplier) LockMigrations() (func(), error) {
+	table, err := ss.GetMaster().TableFor(reflect.TypeOf(model.System{}), false)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := ss.GetMaster().Exec(table.SqlForCreate(true)); err != nil {
+		return nil, fmt.Errorf("unable to create the Systems table: %v", err)
+	}
+
+	lock := &migrationLock{ss: ss, id: model.NewId(), stop: make(chan struct{})}
+
+	waiting := false
+	released := false
+	for {
+		lock.value = lock.nextValue()
+		insertErr := ss.GetMaster().Insert(&model.System{Name: MIGRATION_LOCK_NAME, Value: lock.value})
+		if insertErr == nil {
+			break
+		}
+
+		held, err := ss.GetMaster().SelectStr("SELECT Value FROM Systems WHERE Name = :Name", map[string]interface{}{"Name": MIGRATION_LOCK_NAME})
+		if err != nil {
+			return nil, fmt.Errorf("unable to check the migration lock: %v", err)
+		}
+
+		// The lock may have been released since it couldn't be taken, but if it still can't be then something else is
+		// wrong
+		if held == "" {
+			if released {
+				return nil, fmt.Errorf("unable to take the migration lock: %v", insertErr)
+			}
+			released = true
+			continue
+		}
+
+		if model.GetMillis()-migrationLockRefreshedAt(held) > MIGRATION_LOCK_EXPIRY_MILLISECONDS {
+			mlog.Warn("Taking over the migration lock from a server that stopped while migrating the database")
+			if _, err := ss.GetMaster().Exec("DELETE FROM Systems WHERE Name = :Name AND Value = :Value", map[string]interface{}{"Name": MIGRATION_LOCK_NAME, "Value": held}); err != nil {
+				return nil, fmt.Errorf("unable to remove the expired migration lock: %v", err)
+			}
+			continue
+		}
+
+		if !waiting {
+			mlog.Info("Waiting for another server to finish migrating the database")
+			waiting = true
+		}
+		time.Sleep(MIGRATION_LOCK_POLL_INTERVAL)
+	}
+
+	lock.refreshing.Add(1)
+	go lock.refresh()
+
+	return lock.release, nil
+}
+
+func (l *migrationLock) refresh() {
+	defer l.refreshing.Done()
+
+	ticker := time.NewTicker(MIGRATION_LOCK_REFRESH_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			value := l.nextValue()
+			if _, err := l.ss.GetMaster().Exec("UPDATE Systems SET Value = :NewValue WHERE Name = :Name AND Value = :Value", map[string]interface{}{"NewValue": value, "Name": MIGRATION_LOCK_NAME, "Value": l.value}); err != nil {
+				mlog.Error(fmt.Sprintf("Unable to refresh the migration lock: %v", err))
+			} else {
+				l.value = value
+			}
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+func (l *migrationLock) release() {
+	close(l.stop)
+	l.refreshing.Wait()
+
+	if _, err := l.ss.GetMaster().Exec("DELETE FROM Systems WHERE Name = :Name AND Value = :Value", map[string]interface{}{"Name": MIGRATION_LOCK_NAME, "Value": l.value}); err != nil {
+		mlog.Error(fmt.Sprintf("Unable to release the migration lock: %v", err))
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestLockMigrations(t *testing.T) {
+	for _, st := range storeTypes {
+		st := st
+		t.Run(st.Name, func(t *testing.T) {
+			supplier := NewSqlSupplierForSchemaCheck(*st.Settings)
+			defer supplier.Close()
+
+			lockValue := func() string {
+				value, err := supplier.GetMaster().SelectStr("SELECT Value FROM Systems WHERE Name = :Name", map[string]interface{}{"Name": MIGRATION_LOCK_NAME})
+				require.Nil(t, err)
+				return value
+			}
+
+			t.Run("waits for the lock", func(t *testing.T) {
+				unlock, err := supplier.LockMigrations()
+				require.Nil(t, err)
+				assert.NotEqual(t, "", lockValue())
+
+				locked := make(chan func())
+				go func() {
+					unlock2, err := supplier.LockMigrations()
+					assert.Nil(t, err)
+					locked <- unlock2
+				}()
+
+				select {
+				case <-locked:
+					t.Fatal("should have waited for the lock to be released")
+				case <-time.After(2 * MIGRATION_LOCK_POLL_INTERVAL):
+				}
+
+				unlock()
+
+				select {
+				case unlock2 := <-locked:
+					unlock2()
+				case <-time.After(5 * time.Second):
+					t.Fatal("should have taken the lock once it was released")
+				}
+
+				assert.Equal(t, "", lockValue(), "should have released the lock")
+			})
+
+			t.Run("takes over an expired lock", func(t *testing.T) {
+				expired := model.NewId() + ":" + strconv.FormatInt(model.GetMillis()-MIGRATION_LOCK_EXPIRY_MILLISECONDS-1000, 10)
+				require.Nil(t, supplier.GetMaster().Insert(&model.System{Name: MIGRATION_LOCK_NAME, Value: expired}))
+
+				unlock, err := supplier.LockMigrations()
+				require.Nil(t, err)
+				assert.NotEqual(t, expired, lockValue())
+
+				unlock()
+				assert.Equal(t, "", lockValue())
+			})
+		})
+	}
+}
+
+func TestCheckSchemaIsCurrent(t *testing.T) {
+	for _, st := range storeTypes {
+		st := st
+		t.Run(st.Name, func(t *testing.T) {
+			require.Nil(t, CheckSchemaIsCurrent(*st.Settings))
+
+			supplier := NewSqlSupplierForSchemaCheck(*st.Settings)
+			defer supplier.Close()
+
+			saveSchemaVersion(supplier, VERSION_4_10_0)
+			err := CheckSchemaIsCurrent(*st.Settings)
+			saveSchemaVersion(supplier, model.CurrentVersion)
+			require.NotNil(t, err)
+			assert.Contains(t, err.Error(), VERSION_4_10_0)
+
+			supplier.RemoveIndexIfExists("idx_posts_channel_id_update_at", "Posts")
+			err = CheckSchemaIsCurrent(*st.Settings)
+			supplier.createIndexesIfNotExists()
+			require.NotNil(t, err)
+			assert.Contains(t, err.Error(), "idx_posts_channel_id_update_at")
+
+			require.Nil(t, CheckSchemaIsCurrent(*st.Settings))
+		})
+	}
+}
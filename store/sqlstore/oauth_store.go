@@ -4,6 +4,7 @@
 package sqlstore
 
 import (
+	"database/sql"
 	"net/http"
 	"strings"
 
@@ -43,6 +44,7 @@ func NewSqlOAuthStore(sqlStore SqlStore) store.OAuthStore {
 		tableAccess.ColMap("UserId").SetMaxSize(26)
 		tableAccess.ColMap("Token").SetMaxSize(26)
 		tableAccess.ColMap("RefreshToken").SetMaxSize(26)
+		tableAccess.ColMap("PreviousRefreshToken").SetMaxSize(26)
 		tableAccess.ColMap("RedirectUri").SetMaxSize(256)
 		tableAccess.ColMap("Scope").SetMaxSize(128)
 		tableAccess.SetUniqueTogether("ClientId", "UserId")
@@ -56,6 +58,7 @@ func (as SqlOAuthStore) CreateIndexesIfNotExists() {
 	as.CreateIndexIfNotExists("idx_oauthaccessdata_client_id", "OAuthAccessData", "ClientId")
 	as.CreateIndexIfNotExists("idx_oauthaccessdata_user_id", "OAuthAccessData", "UserId")
 	as.CreateIndexIfNotExists("idx_oauthaccessdata_refresh_token", "OAuthAccessData", "RefreshToken")
+	as.CreateIndexIfNotExists("idx_oauthaccessdata_previous_refresh_token", "OAuthAccessData", "PreviousRefreshToken")
 	as.CreateIndexIfNotExists("idx_oauthauthdata_client_id", "OAuthAuthData", "Code")
 }
 
@@ -234,6 +237,22 @@ func (as SqlOAuthStore) GetAccessDataByRefreshToken(token string) store.StoreCha
 	})
 }
 
+func (as SqlOAuthStore) GetAccessDataByPreviousRefreshToken(token string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		accessData := model.AccessData{}
+
+		if err := as.GetReplica().SelectOne(&accessData, "SELECT * FROM OAuthAccessData WHERE PreviousRefreshToken = :Token", map[string]interface{}{"Token": token}); err != nil {
+			if err == sql.ErrNoRows {
+				result.Err = model.NewAppError("SqlOAuthStore.GetAccessDataByPreviousRefreshToken", "store.sql_oauth.get_access_data_by_previous_refresh_token.app_error", nil, err.Error(), http.StatusNotFound)
+			} else {
+				result.Err = model.NewAppError("SqlOAuthStore.GetAccessDataByPreviousRefreshToken", "store.sql_oauth.get_access_data_by_previous_refresh_token.app_error", nil, err.Error(), http.StatusInternalServerError)
+			}
+		} else {
+			result.Data = &accessData
+		}
+	})
+}
+
 func (as SqlOAuthStore) GetPreviousAccessData(userId, clientId string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		accessData := model.AccessData{}
@@ -257,8 +276,8 @@ func (as SqlOAuthStore) UpdateAccessData(accessData *model.AccessData) store.Sto
 			return
 		}
 
-		if _, err := as.GetMaster().Exec("UPDATE OAuthAccessData SET Token = :Token, ExpiresAt = :ExpiresAt, RefreshToken = :RefreshToken WHERE ClientId = :ClientId AND UserID = :UserId",
-			map[string]interface{}{"Token": accessData.Token, "ExpiresAt": accessData.ExpiresAt, "RefreshToken": accessData.RefreshToken, "ClientId": accessData.ClientId, "UserId": accessData.UserId}); err != nil {
+		if _, err := as.GetMaster().Exec("UPDATE OAuthAccessData SET Token = :Token, ExpiresAt = :ExpiresAt, RefreshToken = :RefreshToken, PreviousRefreshToken = :PreviousRefreshToken WHERE ClientId = :ClientId AND UserID = :UserId",
+			map[string]interface{}{"Token": accessData.Token, "ExpiresAt": accessData.ExpiresAt, "RefreshToken": accessData.RefreshToken, "PreviousRefreshToken": accessData.PreviousRefreshToken, "ClientId": accessData.ClientId, "UserId": accessData.UserId}); err != nil {
 			result.Err = model.NewAppError("SqlOAuthStore.Update", "store.sql_oauth.update_access_data.app_error", nil,
 				"clientId="+accessData.ClientId+",userId="+accessData.UserId+", "+err.Error(), http.StatusInternalServerError)
 		} else {
@@ -0,0 +1,118 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+type SqlPostIdempotencyKeyStore struct {
+	SqlStore
+}
+
+func NewSqlPostIdempotencyKeyStore(sqlStore SqlStore) store.PostIdempotencyKeyStore {
+	s := &SqlPostIdempotencyKeyStore{sqlStore}
+
+	for _, db := range sqlStore.GetAllConns() {
+		table := db.AddTableWithName(model.PostIdempotencyKey{}, "PostIdempotencyKeys").SetKeys(false, "UserId", "IdempotencyKey")
+		table.ColMap("UserId").SetMaxSize(26)
+		table.ColMap("IdempotencyKey").SetMaxSize(model.POST_IDEMPOTENCY_KEY_MAX_LENGTH)
+		table.ColMap("PostId").SetMaxSize(26)
+	}
+
+	return s
+}
+
+func (s SqlPostIdempotencyKeyStore) CreateIndexesIfNotExists() {
+	s.CreateIndexIfNotExists("idx_post_idempotency_keys_create_at", "PostIdempotencyKeys", "CreateAt")
+}
+
+// Reserve saves the key unless the user already has one that was created at or after expiredBefore, replacing an
+// older one. Data is true if the key was saved, so that only the request that saved it creates the post.
+func (s SqlPostIdempotencyKeyStore) Reserve(key *model.PostIdempotencyKey, expiredBefore int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		key.PreSave()
+		if result.Err = key.IsValid(); result.Err != nil {
+			return
+		}
+
+		if _, err := s.GetMaster().Exec("DELETE FROM PostIdempotencyKeys WHERE UserId = :UserId AND IdempotencyKey = :IdempotencyKey AND CreateAt < :ExpiredBefore", map[string]interface{}{"UserId": key.UserId, "IdempotencyKey": key.IdempotencyKey, "ExpiredBefore": expiredBefore}); err != nil {
+			result.Err = model.NewAppError("SqlPostIdempotencyKeyStore.Reserve", "store.sql_post_idempotency_key.reserve.app_error", nil, "user_id="+key.UserId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := s.GetMaster().Insert(key); err != nil {
+			if IsUniqueConstraintError(err, []string{"PRIMARY", "postidempotencykeys_pkey"}) {
+				result.Data = false
+			} else {
+				result.Err = model.NewAppError("SqlPostIdempotencyKeyStore.Reserve", "store.sql_post_idempotency_key.reserve.app_error", nil, "user_id="+key.UserId+", "+err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		result.Data = true
+	})
+}
+
+func (s SqlPostIdempotencyKeyStore) Get(userId string, idempotencyKey string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var key model.PostIdempotencyKey
+
+		if err := s.GetMaster().SelectOne(&key, "SELECT * FROM PostIdempotencyKeys WHERE UserId = :UserId AND IdempotencyKey = :IdempotencyKey", map[string]interface{}{"UserId": userId, "IdempotencyKey": idempotencyKey}); err != nil {
+			if err == sql.ErrNoRows {
+				result.Err = model.NewAppError("SqlPostIdempotencyKeyStore.Get", "store.sql_post_idempotency_key.get.missing.app_error", nil, "user_id="+userId, http.StatusNotFound)
+			} else {
+				result.Err = model.NewAppError("SqlPostIdempotencyKeyStore.Get", "store.sql_post_idempotency_key.get.app_error", nil, "user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		result.Data = &key
+	})
+}
+
+func (s SqlPostIdempotencyKeyStore) SetPostId(userId string, idempotencyKey string, postId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if _, err := s.GetMaster().Exec("UPDATE PostIdempotencyKeys SET PostId = :PostId WHERE UserId = :UserId AND IdempotencyKey = :IdempotencyKey", map[string]interface{}{"UserId": userId, "IdempotencyKey": idempotencyKey, "PostId": postId}); err != nil {
+			result.Err = model.NewAppError("SqlPostIdempotencyKeyStore.SetPostId", "store.sql_post_idempotency_key.set_post_id.app_error", nil, "user_id="+userId+", post_id="+postId+", "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+func (s SqlPostIdempotencyKeyStore) Delete(userId string, idempotencyKey string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if _, err := s.GetMaster().Exec("DELETE FROM PostIdempotencyKeys WHERE UserId = :UserId AND IdempotencyKey = :IdempotencyKey", map[string]interface{}{"UserId": userId, "IdempotencyKey": idempotencyKey}); err != nil {
+			result.Err = model.NewAppError("SqlPostIdempotencyKeyStore.Delete", "store.sql_post_idempotency_key.delete.app_error", nil, "user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+func (s SqlPostIdempotencyKeyStore) PermanentDeleteBatch(endTime int64, limit int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var query string
+		if s.DriverName() == "postgres" {
+			query = "DELETE from PostIdempotencyKeys WHERE (UserId, IdempotencyKey) IN (SELECT UserId, IdempotencyKey FROM PostIdempotencyKeys WHERE CreateAt < :EndTime LIMIT :Limit)"
+		} else {
+			query = "DELETE from PostIdempotencyKeys WHERE CreateAt < :EndTime LIMIT :Limit"
+		}
+
+		sqlResult, err := s.GetMaster().Exec(query, map[string]interface{}{"EndTime": endTime, "Limit": limit})
+		if err != nil {
+			result.Err = model.NewAppError("SqlPostIdempotencyKeyStore.PermanentDeleteBatch", "store.sql_post_idempotency_key.permanent_delete_batch.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rowsAffected, err := sqlResult.RowsAffected()
+		if err != nil {
+			result.Err = model.NewAppError("SqlPostIdempotencyKeyStore.PermanentDeleteBatch", "store.sql_post_idempotency_key.permanent_delete_batch.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = rowsAffected
+	})
+}
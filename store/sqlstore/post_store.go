@@ -12,6 +12,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/mattermost/gorp"
 	"github.com/mattermost/mattermost-server/einterfaces"
 	"github.com/mattermost/mattermost-server/mlog"
 	"github.com/mattermost/mattermost-server/model"
@@ -69,6 +70,14 @@ func NewSqlPostStore(sqlStore SqlStore, metrics einterfaces.MetricsInterface) st
 		table.ColMap("Props").SetMaxSize(8000)
 		table.ColMap("Filenames").SetMaxSize(model.POST_FILENAMES_MAX_RUNES)
 		table.ColMap("FileIds").SetMaxSize(150)
+
+		historyTable := db.AddTableWithName(model.PostHistory{}, "PostsHistory").SetKeys(false, "Id")
+		historyTable.ColMap("Id").SetMaxSize(26)
+		historyTable.ColMap("PostId").SetMaxSize(26)
+		historyTable.ColMap("ChannelId").SetMaxSize(26)
+		historyTable.ColMap("Message").SetMaxSize(model.POST_MESSAGE_MAX_BYTES_V2)
+		historyTable.ColMap("Props").SetMaxSize(8000)
+		historyTable.ColMap("EditorId").SetMaxSize(26)
 	}
 
 	return s
@@ -88,52 +97,102 @@ func (s *SqlPostStore) CreateIndexesIfNotExists() {
 
 	s.CreateFullTextIndexIfNotExists("idx_posts_message_txt", "Posts", "Message")
 	s.CreateFullTextIndexIfNotExists("idx_posts_hashtags_txt", "Posts", "Hashtags")
+
+	s.CreateIndexIfNotExists("idx_posts_history_post_id", "PostsHistory", "PostId")
+	s.CreateIndexIfNotExists("idx_posts_history_channel_id", "PostsHistory", "ChannelId")
+	s.CreateIndexIfNotExists("idx_posts_history_edit_at", "PostsHistory", "EditAt")
 }
 
 func (s *SqlPostStore) Save(post *model.Post) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
-		if len(post.Id) > 0 {
-			result.Err = model.NewAppError("SqlPostStore.Save", "store.sql_post.save.existing.app_error", nil, "id="+post.Id, http.StatusBadRequest)
+		if result.Err = s.prepareToSave(post); result.Err != nil {
 			return
 		}
 
-		var maxPostSize int
-		if result := <-s.GetMaxPostSize(); result.Err != nil {
-			result.Err = model.NewAppError("SqlPostStore.Save", "store.sql_post.save.app_error", nil, "id="+post.Id+", "+result.Err.Error(), http.StatusInternalServerError)
+		if err := savePost(s.GetMaster(), post); err != nil {
+			result.Err = model.NewAppError("SqlPostStore.Save", "store.sql_post.save.app_error", nil, "id="+post.Id+", "+err.Error(), http.StatusInternalServerError)
 			return
-		} else {
-			maxPostSize = result.Data.(int)
 		}
 
-		post.PreSave()
-		if result.Err = post.IsValid(maxPostSize); result.Err != nil {
+		result.Data = post
+	})
+}
+
+// SaveWithWebSocketEvent saves the post and the outbox event for its posted event in the same transaction. The outbox
+// event has the same id as the post.
+func (s *SqlPostStore) SaveWithWebSocketEvent(post *model.Post) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if result.Err = s.prepareToSave(post); result.Err != nil {
 			return
 		}
 
-		if err := s.GetMaster().Insert(post); err != nil {
-			result.Err = model.NewAppError("SqlPostStore.Save", "store.sql_post.save.app_error", nil, "id="+post.Id+", "+err.Error(), http.StatusInternalServerError)
-		} else {
-			time := post.UpdateAt
+		transaction, err := s.GetMaster().Begin()
+		if err != nil {
+			result.Err = model.NewAppError("SqlPostStore.SaveWithWebSocketEvent", "store.sql_post.save.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
 
-			if post.Type != model.POST_JOIN_LEAVE && post.Type != model.POST_ADD_REMOVE &&
-				post.Type != model.POST_JOIN_CHANNEL && post.Type != model.POST_LEAVE_CHANNEL &&
-				post.Type != model.POST_JOIN_TEAM && post.Type != model.POST_LEAVE_TEAM &&
-				post.Type != model.POST_ADD_TO_CHANNEL && post.Type != model.POST_REMOVE_FROM_CHANNEL {
-				s.GetMaster().Exec("UPDATE Channels SET LastPostAt = :LastPostAt, TotalMsgCount = TotalMsgCount + 1 WHERE Id = :ChannelId", map[string]interface{}{"LastPostAt": time, "ChannelId": post.ChannelId})
-			} else {
-				// don't update TotalMsgCount for unimportant messages so that the channel isn't marked as unread
-				s.GetMaster().Exec("UPDATE Channels SET LastPostAt = :LastPostAt WHERE Id = :ChannelId", map[string]interface{}{"LastPostAt": time, "ChannelId": post.ChannelId})
-			}
+		if err := savePost(transaction, post); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlPostStore.SaveWithWebSocketEvent", "store.sql_post.save.app_error", nil, "id="+post.Id+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
 
-			if len(post.RootId) > 0 {
-				s.GetMaster().Exec("UPDATE Posts SET UpdateAt = :UpdateAt WHERE Id = :RootId", map[string]interface{}{"UpdateAt": time, "RootId": post.RootId})
-			}
+		if err := saveWebSocketOutboxEvent(transaction, model.NewPostedWebSocketOutboxEvent(post)); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlPostStore.SaveWithWebSocketEvent", "store.sql_post.save.websocket_event.app_error", nil, "id="+post.Id+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
 
-			result.Data = post
+		if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlPostStore.SaveWithWebSocketEvent", "store.sql_post.save.commit_transaction.app_error", nil, "id="+post.Id+", "+err.Error(), http.StatusInternalServerError)
+			return
 		}
+
+		result.Data = post
 	})
 }
 
+func (s *SqlPostStore) prepareToSave(post *model.Post) *model.AppError {
+	if len(post.Id) > 0 {
+		return model.NewAppError("SqlPostStore.Save", "store.sql_post.save.existing.app_error", nil, "id="+post.Id, http.StatusBadRequest)
+	}
+
+	result := <-s.GetMaxPostSize()
+	if result.Err != nil {
+		return model.NewAppError("SqlPostStore.Save", "store.sql_post.save.app_error", nil, "id="+post.Id+", "+result.Err.Error(), http.StatusInternalServerError)
+	}
+
+	post.PreSave()
+	return post.IsValid(result.Data.(int))
+}
+
+// savePost inserts the post and updates its channel and thread to match. The updates are best effort, so only an
+// error from inserting the post is returned.
+func savePost(executor gorp.SqlExecutor, post *model.Post) error {
+	if err := executor.Insert(post); err != nil {
+		return err
+	}
+
+	time := post.UpdateAt
+
+	if post.Type != model.POST_JOIN_LEAVE && post.Type != model.POST_ADD_REMOVE &&
+		post.Type != model.POST_JOIN_CHANNEL && post.Type != model.POST_LEAVE_CHANNEL &&
+		post.Type != model.POST_JOIN_TEAM && post.Type != model.POST_LEAVE_TEAM &&
+		post.Type != model.POST_ADD_TO_CHANNEL && post.Type != model.POST_REMOVE_FROM_CHANNEL {
+		executor.Exec("UPDATE Channels SET LastPostAt = :LastPostAt, TotalMsgCount = TotalMsgCount + 1 WHERE Id = :ChannelId", map[string]interface{}{"LastPostAt": time, "ChannelId": post.ChannelId})
+	} else {
+		// don't update TotalMsgCount for unimportant messages so that the channel isn't marked as unread
+		executor.Exec("UPDATE Channels SET LastPostAt = :LastPostAt WHERE Id = :ChannelId", map[string]interface{}{"LastPostAt": time, "ChannelId": post.ChannelId})
+	}
+
+	if len(post.RootId) > 0 {
+		executor.Exec("UPDATE Posts SET UpdateAt = :UpdateAt WHERE Id = :RootId", map[string]interface{}{"UpdateAt": time, "RootId": post.RootId})
+	}
+
+	return nil
+}
+
 func (s *SqlPostStore) Update(newPost *model.Post, oldPost *model.Post) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		newPost.UpdateAt = model.GetMillis()
@@ -175,6 +234,42 @@ func (s *SqlPostStore) Update(newPost *model.Post, oldPost *model.Post) store.St
 	})
 }
 
+func (s *SqlPostStore) SaveHistory(history *model.PostHistory) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		history.PreSave()
+
+		var maxPostSize int
+		if result := <-s.GetMaxPostSize(); result.Err != nil {
+			result.Err = model.NewAppError("SqlPostStore.SaveHistory", "store.sql_post.save_history.app_error", nil, "post_id="+history.PostId+", "+result.Err.Error(), http.StatusInternalServerError)
+			return
+		} else {
+			maxPostSize = result.Data.(int)
+		}
+
+		if result.Err = history.IsValid(maxPostSize); result.Err != nil {
+			return
+		}
+
+		if err := s.GetMaster().Insert(history); err != nil {
+			result.Err = model.NewAppError("SqlPostStore.SaveHistory", "store.sql_post.save_history.app_error", nil, "post_id="+history.PostId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = history
+		}
+	})
+}
+
+// GetHistory returns the earlier versions of a post, oldest first.
+func (s *SqlPostStore) GetHistory(postId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var history []*model.PostHistory
+		if _, err := s.GetReplica().Select(&history, "SELECT * FROM PostsHistory WHERE PostId = :PostId ORDER BY EditAt ASC", map[string]interface{}{"PostId": postId}); err != nil {
+			result.Err = model.NewAppError("SqlPostStore.GetHistory", "store.sql_post.get_history.app_error", nil, "post_id="+postId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = history
+		}
+	})
+}
+
 func (s *SqlPostStore) Overwrite(post *model.Post) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		post.UpdateAt = model.GetMillis()
@@ -409,7 +504,13 @@ func (s *SqlPostStore) Delete(postId string, time int64) store.StoreChannel {
 
 func (s *SqlPostStore) permanentDelete(postId string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
-		_, err := s.GetMaster().Exec("DELETE FROM Posts WHERE Id = :Id OR RootId = :RootId", map[string]interface{}{"Id": postId, "RootId": postId})
+		_, err := s.GetMaster().Exec("DELETE FROM PostsHistory WHERE PostId IN (SELECT Id FROM Posts WHERE Id = :Id OR RootId = :RootId)", map[string]interface{}{"Id": postId, "RootId": postId})
+		if err != nil {
+			result.Err = model.NewAppError("SqlPostStore.Delete", "store.sql_post.permanent_delete.app_error", nil, "id="+postId+", err="+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		_, err = s.GetMaster().Exec("DELETE FROM Posts WHERE Id = :Id OR RootId = :RootId", map[string]interface{}{"Id": postId, "RootId": postId})
 		if err != nil {
 			result.Err = model.NewAppError("SqlPostStore.Delete", "store.sql_post.permanent_delete.app_error", nil, "id="+postId+", err="+err.Error(), http.StatusInternalServerError)
 		}
@@ -418,7 +519,13 @@ func (s *SqlPostStore) permanentDelete(postId string) store.StoreChannel {
 
 func (s *SqlPostStore) permanentDeleteAllCommentByUser(userId string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
-		_, err := s.GetMaster().Exec("DELETE FROM Posts WHERE UserId = :UserId AND RootId != ''", map[string]interface{}{"UserId": userId})
+		_, err := s.GetMaster().Exec("DELETE FROM PostsHistory WHERE PostId IN (SELECT Id FROM Posts WHERE UserId = :UserId AND RootId != '')", map[string]interface{}{"UserId": userId})
+		if err != nil {
+			result.Err = model.NewAppError("SqlPostStore.permanentDeleteAllCommentByUser", "store.sql_post.permanent_delete_all_comments_by_user.app_error", nil, "userId="+userId+", err="+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		_, err = s.GetMaster().Exec("DELETE FROM Posts WHERE UserId = :UserId AND RootId != ''", map[string]interface{}{"UserId": userId})
 		if err != nil {
 			result.Err = model.NewAppError("SqlPostStore.permanentDeleteAllCommentByUser", "store.sql_post.permanent_delete_all_comments_by_user.app_error", nil, "userId="+userId+", err="+err.Error(), http.StatusInternalServerError)
 		}
@@ -467,12 +574,54 @@ func (s *SqlPostStore) PermanentDeleteByUser(userId string) store.StoreChannel {
 
 func (s *SqlPostStore) PermanentDeleteByChannel(channelId string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
+		if _, err := s.GetMaster().Exec("DELETE FROM PostsHistory WHERE ChannelId = :ChannelId", map[string]interface{}{"ChannelId": channelId}); err != nil {
+			result.Err = model.NewAppError("SqlPostStore.PermanentDeleteByChannel", "store.sql_post.permanent_delete_by_channel.app_error", nil, "channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
 		if _, err := s.GetMaster().Exec("DELETE FROM Posts WHERE ChannelId = :ChannelId", map[string]interface{}{"ChannelId": channelId}); err != nil {
 			result.Err = model.NewAppError("SqlPostStore.PermanentDeleteByChannel", "store.sql_post.permanent_delete_by_channel.app_error", nil, "channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
 		}
 	})
 }
 
+// PermanentDeleteByChannelBatch deletes up to limit of the channel's posts, and up to limit of the earlier versions
+// of them, returning how many rows were deleted.
+func (s *SqlPostStore) PermanentDeleteByChannelBatch(channelId string, limit int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var queries []string
+		if s.DriverName() == "postgres" {
+			queries = []string{
+				"DELETE FROM PostsHistory WHERE Id = any (array (SELECT Id FROM PostsHistory WHERE ChannelId = :ChannelId LIMIT :Limit))",
+				"DELETE FROM Posts WHERE Id = any (array (SELECT Id FROM Posts WHERE ChannelId = :ChannelId LIMIT :Limit))",
+			}
+		} else {
+			queries = []string{
+				"DELETE FROM PostsHistory WHERE ChannelId = :ChannelId LIMIT :Limit",
+				"DELETE FROM Posts WHERE ChannelId = :ChannelId LIMIT :Limit",
+			}
+		}
+
+		var rowsAffected int64
+		for _, query := range queries {
+			sqlResult, err := s.GetMaster().Exec(query, map[string]interface{}{"ChannelId": channelId, "Limit": limit})
+			if err != nil {
+				result.Err = model.NewAppError("SqlPostStore.PermanentDeleteByChannelBatch", "store.sql_post.permanent_delete_by_channel_batch.app_error", nil, "channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			count, err := sqlResult.RowsAffected()
+			if err != nil {
+				result.Err = model.NewAppError("SqlPostStore.PermanentDeleteByChannelBatch", "store.sql_post.permanent_delete_by_channel_batch.app_error", nil, "channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			rowsAffected += count
+		}
+
+		result.Data = rowsAffected
+	})
+}
+
 func (s *SqlPostStore) GetPosts(channelId string, offset int, limit int, allowFromCache bool) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		if limit > 1000 {
@@ -812,8 +961,18 @@ func (s *SqlPostStore) Search(teamId string, userId string, params *model.Search
 							AND DeleteAt = 0
 							CHANNEL_FILTER)
 				SEARCH_CLAUSE
-				ORDER BY CreateAt DESC
-			LIMIT 100`
+				ORDER BY CreateAt DESC, Id DESC
+			LIMIT :Limit`
+
+		queryParams["Limit"] = model.POST_SEARCH_DEFAULT_LIMIT
+		if params.Limit > 0 {
+			queryParams["Limit"] = params.Limit
+		}
+
+		if params.MaxCreateAt > 0 {
+			queryParams["MaxCreateAt"] = params.MaxCreateAt
+			searchQuery = strings.Replace(searchQuery, "POST_FILTER", "AND CreateAt <= :MaxCreateAt POST_FILTER", 1)
+		}
 
 		if len(params.InChannels) > 1 {
 			inClause := ":InChannel0"
@@ -1088,6 +1247,80 @@ func (s *SqlPostStore) GetPostsCreatedAt(channelId string, time int64) store.Sto
 	})
 }
 
+// GetPostsByUser returns every post the user has written, including deleted ones, oldest first.
+func (s *SqlPostStore) GetPostsByUser(userId string, offset int, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var posts []*model.Post
+		if _, err := s.GetReplica().Select(&posts, "SELECT * FROM Posts WHERE UserId = :UserId ORDER BY CreateAt ASC, Id ASC LIMIT :Limit OFFSET :Offset", map[string]interface{}{"UserId": userId, "Offset": offset, "Limit": limit}); err != nil {
+			result.Err = model.NewAppError("SqlPostStore.GetPostsByUser", "store.sql_post.get_posts_by_user.app_error", nil, "user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = posts
+		}
+	})
+}
+
+// GetPostsWithMessageContaining returns posts whose message contains term, ordered by id so that callers
+// can page through them with afterId even while changing the messages. The term is matched with LIKE, so
+// an underscore in it matches any character and callers should check the messages they get back.
+func (s *SqlPostStore) GetPostsWithMessageContaining(term string, afterId string, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var posts []*model.Post
+		if _, err := s.GetReplica().Select(&posts, "SELECT * FROM Posts WHERE Id > :AfterId AND Message LIKE :Term ORDER BY Id ASC LIMIT :Limit", map[string]interface{}{"AfterId": afterId, "Term": "%" + term + "%", "Limit": limit}); err != nil {
+			result.Err = model.NewAppError("SqlPostStore.GetPostsWithMessageContaining", "store.sql_post.get_posts_with_message_containing.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = posts
+		}
+	})
+}
+
+// GetPostsWithPropsLargerThan returns the sizes of the props of posts whose serialized props are more than size
+// bytes, ordered by id so that callers can page through them with afterId. The props themselves aren't loaded.
+func (s *SqlPostStore) GetPostsWithPropsLargerThan(size int, afterId string, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var sizes []*model.PostPropsSize
+		if _, err := s.GetReplica().Select(&sizes, `
+			SELECT
+				Id AS PostId, ChannelId, UserId, CreateAt, OCTET_LENGTH(Props) AS Size
+			FROM
+				Posts
+			WHERE
+				Id > :AfterId
+				AND OCTET_LENGTH(Props) > :Size
+			ORDER BY
+				Id ASC
+			LIMIT :Limit`, map[string]interface{}{"AfterId": afterId, "Size": size, "Limit": limit}); err != nil {
+			result.Err = model.NewAppError("SqlPostStore.GetPostsWithPropsLargerThan", "store.sql_post.get_posts_with_props_larger_than.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = sizes
+		}
+	})
+}
+
+// GetPostsForChannelExport returns a batch of the posts in a channel that haven't been deleted, ordered by CreateAt
+// and Id. The batch starts after the post with afterCreateAt and afterId, so a batch is fetched by passing the last
+// post of the one before.
+func (s *SqlPostStore) GetPostsForChannelExport(channelId string, afterCreateAt int64, afterId string, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var posts []*model.Post
+		if _, err := s.GetReplica().Select(&posts, `
+			SELECT
+				*
+			FROM
+				Posts
+			WHERE
+				ChannelId = :ChannelId
+				AND DeleteAt = 0
+				AND (CreateAt > :AfterCreateAt OR (CreateAt = :AfterCreateAt AND Id > :AfterId))
+			ORDER BY
+				CreateAt, Id
+			LIMIT :Limit`, map[string]interface{}{"ChannelId": channelId, "AfterCreateAt": afterCreateAt, "AfterId": afterId, "Limit": limit}); err != nil {
+			result.Err = model.NewAppError("SqlPostStore.GetPostsForChannelExport", "store.sql_post.get_posts_for_channel_export.app_error", nil, "channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = posts
+		}
+	})
+}
+
 func (s *SqlPostStore) GetPostsByIds(postIds []string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		keys := bytes.Buffer{}
@@ -1159,24 +1392,35 @@ func (s *SqlPostStore) GetPostsBatchForIndexing(startTime int64, endTime int64,
 func (s *SqlPostStore) PermanentDeleteBatch(endTime int64, limit int64) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		var query string
+		var historyQuery string
 		if s.DriverName() == "postgres" {
 			query = "DELETE from Posts WHERE Id = any (array (SELECT Id FROM Posts WHERE CreateAt < :EndTime LIMIT :Limit))"
+			historyQuery = "DELETE from PostsHistory WHERE Id = any (array (SELECT Id FROM PostsHistory WHERE EditAt < :EndTime LIMIT :Limit))"
 		} else {
 			query = "DELETE from Posts WHERE CreateAt < :EndTime LIMIT :Limit"
+			historyQuery = "DELETE from PostsHistory WHERE EditAt < :EndTime LIMIT :Limit"
 		}
 
-		sqlResult, err := s.GetMaster().Exec(query, map[string]interface{}{"EndTime": endTime, "Limit": limit})
-		if err != nil {
-			result.Err = model.NewAppError("SqlPostStore.PermanentDeleteBatch", "store.sql_post.permanent_delete_batch.app_error", nil, ""+err.Error(), http.StatusInternalServerError)
-		} else {
-			rowsAffected, err1 := sqlResult.RowsAffected()
-			if err1 != nil {
+		var rowsAffected int64
+		// A post is always created before it's edited, so removing its earlier versions along with it keeps
+		// anything older than the end time from being left behind.
+		for _, q := range []string{query, historyQuery} {
+			sqlResult, err := s.GetMaster().Exec(q, map[string]interface{}{"EndTime": endTime, "Limit": limit})
+			if err != nil {
+				result.Err = model.NewAppError("SqlPostStore.PermanentDeleteBatch", "store.sql_post.permanent_delete_batch.app_error", nil, ""+err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			count, err := sqlResult.RowsAffected()
+			if err != nil {
 				result.Err = model.NewAppError("SqlPostStore.PermanentDeleteBatch", "store.sql_post.permanent_delete_batch.app_error", nil, ""+err.Error(), http.StatusInternalServerError)
 				result.Data = int64(0)
-			} else {
-				result.Data = rowsAffected
+				return
 			}
+			rowsAffected += count
 		}
+
+		result.Data = rowsAffected
 	})
 }
 
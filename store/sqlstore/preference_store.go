@@ -5,6 +5,8 @@ package sqlstore
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/mattermost/gorp"
 
@@ -56,114 +58,98 @@ func (s SqlPreferenceStore) DeleteUnusedFeatures() {
 
 func (s SqlPreferenceStore) Save(preferences *model.Preferences) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
+		// as when saving them one at a time, the last of several preferences with the same key wins
+		var batch model.Preferences
+		positions := make(map[string]int, len(*preferences))
+		for _, preference := range *preferences {
+			preference.PreUpdate()
+
+			if result.Err = preference.IsValid(); result.Err != nil {
+				return
+			}
+
+			key := preference.UserId + ":" + preference.Category + ":" + preference.Name
+			if i, ok := positions[key]; ok {
+				batch[i] = preference
+			} else {
+				positions[key] = len(batch)
+				batch = append(batch, preference)
+			}
+		}
+
 		// wrap in a transaction so that if one fails, everything fails
 		transaction, err := s.GetMaster().Begin()
 		if err != nil {
 			result.Err = model.NewAppError("SqlPreferenceStore.Save", "store.sql_preference.save.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
-		} else {
-			for _, preference := range *preferences {
-				if upsertResult := s.save(transaction, &preference); upsertResult.Err != nil {
-					*result = upsertResult
-					break
-				}
+			return
+		}
+
+		for start := 0; start < len(batch); start += model.PREFERENCES_MAX_UPDATE {
+			end := start + model.PREFERENCES_MAX_UPDATE
+			if end > len(batch) {
+				end = len(batch)
+			}
+
+			if result.Err = s.saveBatch(transaction, batch[start:end]); result.Err != nil {
+				break
 			}
+		}
 
-			if result.Err == nil {
-				if err := transaction.Commit(); err != nil {
-					// don't need to rollback here since the transaction is already closed
-					result.Err = model.NewAppError("SqlPreferenceStore.Save", "store.sql_preference.save.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
-				} else {
-					result.Data = len(*preferences)
-				}
+		if result.Err == nil {
+			if err := transaction.Commit(); err != nil {
+				// don't need to rollback here since the transaction is already closed
+				result.Err = model.NewAppError("SqlPreferenceStore.Save", "store.sql_preference.save.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
 			} else {
-				if err := transaction.Rollback(); err != nil {
-					result.Err = model.NewAppError("SqlPreferenceStore.Save", "store.sql_preference.save.rollback_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
-				}
+				result.Data = len(*preferences)
+			}
+		} else {
+			if err := transaction.Rollback(); err != nil {
+				result.Err = model.NewAppError("SqlPreferenceStore.Save", "store.sql_preference.save.rollback_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
 			}
 		}
 	})
 }
 
-func (s SqlPreferenceStore) save(transaction *gorp.Transaction, preference *model.Preference) store.StoreResult {
-	result := store.StoreResult{}
-
-	preference.PreUpdate()
-
-	if result.Err = preference.IsValid(); result.Err != nil {
-		return result
+// saveBatch upserts the given preferences, which must have distinct keys, with a single INSERT statement.
+func (s SqlPreferenceStore) saveBatch(transaction *gorp.Transaction, preferences model.Preferences) *model.AppError {
+	params := map[string]interface{}{}
+	values := make([]string, len(preferences))
+	keys := make([]string, len(preferences))
+	for i, preference := range preferences {
+		suffix := strconv.Itoa(i)
+		params["UserId"+suffix] = preference.UserId
+		params["Category"+suffix] = preference.Category
+		params["Name"+suffix] = preference.Name
+		params["Value"+suffix] = preference.Value
+
+		values[i] = "(:UserId" + suffix + ", :Category" + suffix + ", :Name" + suffix + ", :Value" + suffix + ")"
+		keys[i] = "(UserId = :UserId" + suffix + " AND Category = :Category" + suffix + " AND Name = :Name" + suffix + ")"
 	}
 
-	params := map[string]interface{}{
-		"UserId":   preference.UserId,
-		"Category": preference.Category,
-		"Name":     preference.Name,
-		"Value":    preference.Value,
-	}
+	query := `INSERT INTO
+			Preferences
+			(UserId, Category, Name, Value)
+		VALUES
+			` + strings.Join(values, ", ")
 
 	if s.DriverName() == model.DATABASE_DRIVER_MYSQL {
-		if _, err := transaction.Exec(
-			`INSERT INTO
-				Preferences
-				(UserId, Category, Name, Value)
-			VALUES
-				(:UserId, :Category, :Name, :Value)
-			ON DUPLICATE KEY UPDATE
-				Value = :Value`, params); err != nil {
-			result.Err = model.NewAppError("SqlPreferenceStore.save", "store.sql_preference.save.updating.app_error", nil, err.Error(), http.StatusInternalServerError)
-		}
+		query += `
+		ON DUPLICATE KEY UPDATE
+			Value = VALUES(Value)`
 	} else if s.DriverName() == model.DATABASE_DRIVER_POSTGRES {
-		// postgres has no way to upsert values until version 9.5 and trying inserting and then updating causes transactions to abort
-		count, err := transaction.SelectInt(
-			`SELECT
-				count(0)
-			FROM
-				Preferences
-			WHERE
-				UserId = :UserId
-				AND Category = :Category
-				AND Name = :Name`, params)
-		if err != nil {
-			result.Err = model.NewAppError("SqlPreferenceStore.save", "store.sql_preference.save.updating.app_error", nil, err.Error(), http.StatusInternalServerError)
-			return result
-		}
-
-		if count == 1 {
-			s.update(transaction, preference)
-		} else {
-			s.insert(transaction, preference)
+		// postgres has no way to upsert values until version 9.5, so replace the existing rows instead
+		if _, err := transaction.Exec(`DELETE FROM Preferences WHERE `+strings.Join(keys, " OR "), params); err != nil {
+			return model.NewAppError("SqlPreferenceStore.saveBatch", "store.sql_preference.save.updating.app_error", nil, err.Error(), http.StatusInternalServerError)
 		}
 	} else {
-		result.Err = model.NewAppError("SqlPreferenceStore.save", "store.sql_preference.save.missing_driver.app_error", nil, "Failed to update preference because of missing driver", http.StatusNotImplemented)
+		return model.NewAppError("SqlPreferenceStore.saveBatch", "store.sql_preference.save.missing_driver.app_error", nil, "Failed to update preference because of missing driver", http.StatusNotImplemented)
 	}
 
-	return result
-}
-
-func (s SqlPreferenceStore) insert(transaction *gorp.Transaction, preference *model.Preference) store.StoreResult {
-	result := store.StoreResult{}
-
-	if err := transaction.Insert(preference); err != nil {
-		if IsUniqueConstraintError(err, []string{"UserId", "preferences_pkey"}) {
-			result.Err = model.NewAppError("SqlPreferenceStore.insert", "store.sql_preference.insert.exists.app_error", nil,
-				"user_id="+preference.UserId+", category="+preference.Category+", name="+preference.Name+", "+err.Error(), http.StatusBadRequest)
-		} else {
-			result.Err = model.NewAppError("SqlPreferenceStore.insert", "store.sql_preference.insert.save.app_error", nil,
-				"user_id="+preference.UserId+", category="+preference.Category+", name="+preference.Name+", "+err.Error(), http.StatusInternalServerError)
-		}
+	if _, err := transaction.Exec(query, params); err != nil {
+		return model.NewAppError("SqlPreferenceStore.saveBatch", "store.sql_preference.save.updating.app_error", nil, err.Error(), http.StatusInternalServerError)
 	}
 
-	return result
-}
-
-func (s SqlPreferenceStore) update(transaction *gorp.Transaction, preference *model.Preference) store.StoreResult {
-	result := store.StoreResult{}
-
-	if _, err := transaction.Update(preference); err != nil {
-		result.Err = model.NewAppError("SqlPreferenceStore.update", "store.sql_preference.update.app_error", nil,
-			"user_id="+preference.UserId+", category="+preference.Category+", name="+preference.Name+", "+err.Error(), http.StatusInternalServerError)
-	}
-
-	return result
+	return nil
 }
 
 func (s SqlPreferenceStore) Get(userId string, category string, name string) store.StoreChannel {
@@ -205,6 +191,26 @@ func (s SqlPreferenceStore) GetCategory(userId string, category string) store.St
 	})
 }
 
+// GetUserIdsByCategoryAndName returns the ids of the users that have a preference with the given category and name.
+func (s SqlPreferenceStore) GetUserIdsByCategoryAndName(category string, name string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var userIds []string
+
+		if _, err := s.GetReplica().Select(&userIds,
+			`SELECT
+				UserId
+			FROM
+				Preferences
+			WHERE
+				Category = :Category
+				AND Name = :Name`, map[string]interface{}{"Category": category, "Name": name}); err != nil {
+			result.Err = model.NewAppError("SqlPreferenceStore.GetUserIdsByCategoryAndName", "store.sql_preference.get_user_ids.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = userIds
+		}
+	})
+}
+
 func (s SqlPreferenceStore) GetAll(userId string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		var preferences model.Preferences
@@ -223,6 +229,36 @@ func (s SqlPreferenceStore) GetAll(userId string) store.StoreChannel {
 	})
 }
 
+func (s SqlPreferenceStore) GetPage(userId string, category string, offset int, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var preferences model.Preferences
+
+		query := `SELECT
+				*
+			FROM
+				Preferences
+			WHERE
+				UserId = :UserId`
+		if category != "" {
+			query += `
+				AND Category = :Category`
+		}
+		query += `
+			ORDER BY
+				Category, Name
+			LIMIT
+				:Limit
+			OFFSET
+				:Offset`
+
+		if _, err := s.GetReplica().Select(&preferences, query, map[string]interface{}{"UserId": userId, "Category": category, "Limit": limit, "Offset": offset}); err != nil {
+			result.Err = model.NewAppError("SqlPreferenceStore.GetPage", "store.sql_preference.get_page.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = preferences
+		}
+	})
+}
+
 func (s SqlPreferenceStore) PermanentDeleteByUser(userId string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		if _, err := s.GetMaster().Exec(
@@ -331,3 +367,55 @@ func (s SqlPreferenceStore) CleanupFlagsBatch(limit int64) store.StoreChannel {
 		}
 	})
 }
+
+func (s SqlPreferenceStore) CleanupChannelsBatch(limit int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		params := map[string]interface{}{"Limit": limit}
+		categories := make([]string, len(model.ChannelPreferenceCategories))
+		for i, category := range model.ChannelPreferenceCategories {
+			key := "Category" + strconv.Itoa(i)
+			params[key] = category
+			categories[i] = ":" + key
+		}
+		inCategories := strings.Join(categories, ", ")
+
+		query :=
+			`DELETE FROM
+				Preferences
+			WHERE
+				Category IN (` + inCategories + `)
+				AND Name IN (
+					SELECT
+						*
+					FROM (
+						SELECT
+							Preferences.Name
+						FROM
+							Preferences
+						LEFT JOIN
+							Channels
+						ON
+							Preferences.Name = Channels.Id
+						WHERE
+							Preferences.Category IN (` + inCategories + `)
+							AND Channels.Id IS null
+						LIMIT
+							:Limit
+					)
+					AS t
+				)`
+
+		sqlResult, err := s.GetMaster().Exec(query, params)
+		if err != nil {
+			result.Err = model.NewAppError("SqlPreferenceStore.CleanupChannelsBatch", "store.sql_preference.cleanup_channels_batch.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			rowsAffected, err := sqlResult.RowsAffected()
+			if err != nil {
+				result.Err = model.NewAppError("SqlPreferenceStore.CleanupChannelsBatch", "store.sql_preference.cleanup_channels_batch.app_error", nil, err.Error(), http.StatusInternalServerError)
+				result.Data = int64(0)
+			} else {
+				result.Data = rowsAffected
+			}
+		}
+	})
+}
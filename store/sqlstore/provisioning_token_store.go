@@ -0,0 +1,105 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+type SqlProvisioningTokenStore struct {
+	SqlStore
+}
+
+func NewSqlProvisioningTokenStore(sqlStore SqlStore) store.ProvisioningTokenStore {
+	s := &SqlProvisioningTokenStore{sqlStore}
+
+	for _, db := range sqlStore.GetAllConns() {
+		table := db.AddTableWithName(model.ProvisioningToken{}, "ProvisioningTokens").SetKeys(false, "Id")
+		table.ColMap("Id").SetMaxSize(26)
+		table.ColMap("Token").SetMaxSize(26).SetUnique(true)
+		table.ColMap("CreatorId").SetMaxSize(26)
+		table.ColMap("Description").SetMaxSize(model.PROVISIONING_TOKEN_DESCRIPTION_MAX_LENGTH)
+	}
+
+	return s
+}
+
+func (s SqlProvisioningTokenStore) CreateIndexesIfNotExists() {
+	s.CreateIndexIfNotExists("idx_provisioning_tokens_token", "ProvisioningTokens", "Token")
+}
+
+func (s SqlProvisioningTokenStore) Save(token *model.ProvisioningToken) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		token.PreSave()
+
+		if result.Err = token.IsValid(); result.Err != nil {
+			return
+		}
+
+		if err := s.GetMaster().Insert(token); err != nil {
+			result.Err = model.NewAppError("SqlProvisioningTokenStore.Save", "store.sql_provisioning_token.save.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = token
+		}
+	})
+}
+
+func (s SqlProvisioningTokenStore) Get(tokenId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		token := model.ProvisioningToken{}
+
+		if err := s.GetReplica().SelectOne(&token, "SELECT * FROM ProvisioningTokens WHERE Id = :Id", map[string]interface{}{"Id": tokenId}); err != nil {
+			if err == sql.ErrNoRows {
+				result.Err = model.NewAppError("SqlProvisioningTokenStore.Get", "store.sql_provisioning_token.get.app_error", nil, "id="+tokenId+", "+err.Error(), http.StatusNotFound)
+			} else {
+				result.Err = model.NewAppError("SqlProvisioningTokenStore.Get", "store.sql_provisioning_token.get.app_error", nil, "id="+tokenId+", "+err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		result.Data = &token
+	})
+}
+
+func (s SqlProvisioningTokenStore) GetByToken(tokenString string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		token := model.ProvisioningToken{}
+
+		if err := s.GetReplica().SelectOne(&token, "SELECT * FROM ProvisioningTokens WHERE Token = :Token", map[string]interface{}{"Token": tokenString}); err != nil {
+			if err == sql.ErrNoRows {
+				result.Err = model.NewAppError("SqlProvisioningTokenStore.GetByToken", "store.sql_provisioning_token.get_by_token.app_error", nil, err.Error(), http.StatusNotFound)
+			} else {
+				result.Err = model.NewAppError("SqlProvisioningTokenStore.GetByToken", "store.sql_provisioning_token.get_by_token.app_error", nil, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		result.Data = &token
+	})
+}
+
+func (s SqlProvisioningTokenStore) GetAll(offset int, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		tokens := []*model.ProvisioningToken{}
+
+		if _, err := s.GetReplica().Select(&tokens, "SELECT * FROM ProvisioningTokens ORDER BY CreateAt LIMIT :Limit OFFSET :Offset", map[string]interface{}{"Offset": offset, "Limit": limit}); err != nil {
+			result.Err = model.NewAppError("SqlProvisioningTokenStore.GetAll", "store.sql_provisioning_token.get_all.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = tokens
+	})
+}
+
+func (s SqlProvisioningTokenStore) Delete(tokenId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if _, err := s.GetMaster().Exec("DELETE FROM ProvisioningTokens WHERE Id = :Id", map[string]interface{}{"Id": tokenId}); err != nil {
+			result.Err = model.NewAppError("SqlProvisioningTokenStore.Delete", "store.sql_provisioning_token.delete.app_error", nil, "id="+tokenId+", "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"context"
+	dbsql "database/sql"
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/mattermost/gorp"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+// SetReadOnly turns read-only mode on or off. While it's on, queries that would normally go to the master database
+// still go there, but anything that would make changes fails with store.ErrReadOnly.
+func (ss *SqlSupplier) SetReadOnly(readOnly bool) {
+	ss.masterLock.Lock()
+	ss.readOnly = readOnly
+	old := ss.updateReadOnlyMaster()
+	ss.masterLock.Unlock()
+
+	closeConnection(old)
+}
+
+// IsReadOnly returns true while writes are refused, either because read-only mode has been turned on or because the
+// master database is down and reads have fallen back to a replica.
+func (ss *SqlSupplier) IsReadOnly() bool {
+	ss.masterLock.RLock()
+	defer ss.masterLock.RUnlock()
+	return ss.readOnlyMaster != nil
+}
+
+// fallBackToReplica makes the store read-only while the master database is down, using the first replica that can be
+// reached in its place. It returns false if there are no replicas or none of them can be reached either.
+func (ss *SqlSupplier) fallBackToReplica() bool {
+	for i, replica := range ss.replicas {
+		if err := pingConnection(replica.Db); err != nil {
+			mlog.Warn(fmt.Sprintf("Failed to ping replica database replica=%v err=%v", i, err))
+			continue
+		}
+
+		ss.masterLock.Lock()
+		ss.fallbackDataSource = ss.settings.DataSourceReplicas[i]
+		old := ss.updateReadOnlyMaster()
+		ss.masterLock.Unlock()
+
+		closeConnection(old)
+
+		mlog.Warn(fmt.Sprintf("Falling back to read-only mode using replica database replica=%v", i))
+		ss.incrementHealthEvent(DB_HEALTH_EVENT_READ_ONLY)
+
+		return true
+	}
+
+	return false
+}
+
+// stopFallingBackToReplica stops the store from being read-only once the master database has recovered, unless
+// read-only mode has been turned on.
+func (ss *SqlSupplier) stopFallingBackToReplica() {
+	ss.masterLock.Lock()
+	ss.fallbackDataSource = ""
+	old := ss.updateReadOnlyMaster()
+	ss.masterLock.Unlock()
+
+	closeConnection(old)
+}
+
+// updateReadOnlyMaster opens or closes the read-only connection pool used in place of the master database to match
+// the current mode, returning the connection pool that was replaced, if any, so that it can be closed once masterLock
+// has been released. It must be called with masterLock held.
+func (ss *SqlSupplier) updateReadOnlyMaster() *dbsql.DB {
+	dataSource := ss.fallbackDataSource
+	if dataSource == "" && ss.readOnly {
+		dataSource = ss.masterDataSource
+	}
+
+	if ss.readOnlyMaster != nil && ss.readOnlyDataSource == dataSource {
+		return nil
+	}
+
+	var old *dbsql.DB
+	if ss.readOnlyMaster != nil {
+		old = ss.readOnlyMaster.Db
+		ss.readOnlyMaster = nil
+	}
+
+	if dataSource != "" {
+		db := dbsql.OpenDB(&readOnlyConnector{driver: ss.master.Db.Driver(), dataSource: dataSource})
+		configureConnection(db, ss.settings)
+
+		master := *ss.master
+		master.Db = db
+		ss.readOnlyMaster = &master
+	}
+	ss.readOnlyDataSource = dataSource
+
+	return old
+}
+
+// writableMaster returns the master database, even while the store is read-only.
+func (ss *SqlSupplier) writableMaster() *gorp.DbMap {
+	ss.masterLock.RLock()
+	defer ss.masterLock.RUnlock()
+	return ss.master
+}
+
+func closeConnection(db *dbsql.DB) {
+	if db != nil {
+		db.Close()
+	}
+}
+
+// readOnlyConnector opens connections that can run queries but that fail with store.ErrReadOnly for anything else,
+// including starting a transaction.
+type readOnlyConnector struct {
+	driver     driver.Driver
+	dataSource string
+}
+
+func (c *readOnlyConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dataSource)
+	if err != nil {
+		return nil, err
+	}
+
+	return &readOnlyConn{conn}, nil
+}
+
+func (c *readOnlyConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// readOnlyConn doesn't implement driver.Execer so that statements are always prepared and run by readOnlyStmt.
+type readOnlyConn struct {
+	driver.Conn
+}
+
+func (c *readOnlyConn) Prepare(query string) (driver.Stmt, error) {
+	stmt, err := c.Conn.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &readOnlyStmt{stmt}, nil
+}
+
+func (c *readOnlyConn) Begin() (driver.Tx, error) {
+	return nil, store.ErrReadOnly
+}
+
+func (c *readOnlyConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if queryer, ok := c.Conn.(driver.QueryerContext); ok {
+		return queryer.QueryContext(ctx, query, args)
+	}
+
+	return nil, driver.ErrSkip
+}
+
+func (c *readOnlyConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+
+	return nil
+}
+
+func (c *readOnlyConn) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+
+	return driver.ErrSkip
+}
+
+type readOnlyStmt struct {
+	driver.Stmt
+}
+
+func (s *readOnlyStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, store.ErrReadOnly
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func waitForReadOnly(t *testing.T, supplier *SqlSupplier, readOnly bool) {
+	deadline := time.Now().Add(30 * time.Second)
+	for supplier.IsReadOnly() != readOnly {
+		if time.Now().After(deadline) {
+			require.FailNow(t, "timed out waiting for read-only mode to change", "read_only=%v", readOnly)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func assertReadOnly(t *testing.T, supplier *SqlSupplier) {
+	result := <-supplier.System().Get()
+	require.Nil(t, result.Err, "reads should still work")
+
+	result = <-supplier.System().Save(&model.System{Name: model.NewId(), Value: "value"})
+	require.NotNil(t, result.Err)
+	assert.Equal(t, store.READ_ONLY_ERROR, result.Err.Id)
+
+	_, err := supplier.GetMaster().Begin()
+	assert.Equal(t, store.ErrReadOnly, err, "transactions shouldn't be started")
+}
+
+func TestReadOnly(t *testing.T) {
+	for _, st := range storeTypes {
+		st := st
+		t.Run(st.Name, func(t *testing.T) {
+			t.Run("SetReadOnly", func(t *testing.T) {
+				supplier := NewSqlSupplier(*st.Settings, nil, nil)
+				defer supplier.Close()
+				assert.False(t, supplier.IsReadOnly())
+
+				supplier.SetReadOnly(true)
+				assert.True(t, supplier.IsReadOnly())
+				assertReadOnly(t, supplier)
+
+				supplier.SetReadOnly(false)
+				assert.False(t, supplier.IsReadOnly())
+				result := <-supplier.System().Save(&model.System{Name: model.NewId(), Value: "value"})
+				assert.Nil(t, result.Err)
+			})
+
+			t.Run("FallBackToReplica", func(t *testing.T) {
+				target := databaseAddressPattern.FindString(*st.Settings.DataSource)
+				require.NotEmpty(t, target)
+
+				proxy := newToggleProxy(t, target)
+				defer proxy.Close()
+
+				settings := *st.Settings
+				settings.DataSourceReplicas = []string{*st.Settings.DataSource}
+				supplier := newProxiedSupplier(t, &settings, proxy, "")
+				defer supplier.Close()
+
+				proxy.SetDown(true)
+				waitForHealth(t, supplier, false)
+				waitForReadOnly(t, supplier, true)
+				assertReadOnly(t, supplier)
+
+				proxy.SetDown(false)
+				waitForHealth(t, supplier, true)
+				waitForReadOnly(t, supplier, false)
+			})
+		})
+	}
+}
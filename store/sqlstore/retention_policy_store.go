@@ -0,0 +1,447 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+type SqlRetentionPolicyStore struct {
+	SqlStore
+}
+
+func NewSqlRetentionPolicyStore(sqlStore SqlStore) store.RetentionPolicyStore {
+	s := &SqlRetentionPolicyStore{
+		SqlStore: sqlStore,
+	}
+
+	for _, db := range sqlStore.GetAllConns() {
+		tablePolicies := db.AddTableWithName(model.RetentionPolicy{}, "RetentionPolicies").SetKeys(false, "Id")
+		tablePolicies.ColMap("Id").SetMaxSize(26)
+		tablePolicies.ColMap("DisplayName").SetMaxSize(model.RETENTION_POLICY_DISPLAY_NAME_MAX_RUNES)
+
+		tableChannels := db.AddTableWithName(model.RetentionPolicyChannel{}, "RetentionPoliciesChannels").SetKeys(false, "ChannelId")
+		tableChannels.ColMap("PolicyId").SetMaxSize(26)
+		tableChannels.ColMap("ChannelId").SetMaxSize(26)
+
+		tableTeams := db.AddTableWithName(model.RetentionPolicyTeam{}, "RetentionPoliciesTeams").SetKeys(false, "TeamId")
+		tableTeams.ColMap("PolicyId").SetMaxSize(26)
+		tableTeams.ColMap("TeamId").SetMaxSize(26)
+	}
+
+	return s
+}
+
+func (s SqlRetentionPolicyStore) CreateIndexesIfNotExists() {
+	s.CreateIndexIfNotExists("idx_retentionpolicieschannels_policy_id", "RetentionPoliciesChannels", "PolicyId")
+	s.CreateIndexIfNotExists("idx_retentionpoliciesteams_policy_id", "RetentionPoliciesTeams", "PolicyId")
+}
+
+// retentionScopeClause limits a query joined with Channels to the channels governed by a policy. The
+// channels of a team assigned to a policy are governed by it unless they're assigned to a policy
+// themselves. An empty policy id selects the channels that aren't governed by any policy.
+func retentionScopeClause(policyId string) string {
+	if policyId == "" {
+		return `
+			Channels.Id NOT IN (SELECT ChannelId FROM RetentionPoliciesChannels)
+			AND Channels.TeamId NOT IN (SELECT TeamId FROM RetentionPoliciesTeams)`
+	}
+
+	return `
+		(
+			Channels.Id IN (SELECT ChannelId FROM RetentionPoliciesChannels WHERE PolicyId = :PolicyId)
+			OR (
+				Channels.TeamId IN (SELECT TeamId FROM RetentionPoliciesTeams WHERE PolicyId = :PolicyId)
+				AND Channels.Id NOT IN (SELECT ChannelId FROM RetentionPoliciesChannels)
+			)
+		)`
+}
+
+// buildIdListQuery adds the ids to the query parameters and returns the matching placeholders.
+func buildIdListQuery(prefix string, ids []string, props map[string]interface{}) string {
+	query := ""
+	for index, id := range ids {
+		if len(query) > 0 {
+			query += ", "
+		}
+
+		props[prefix+strconv.Itoa(index)] = id
+		query += ":" + prefix + strconv.Itoa(index)
+	}
+
+	return query
+}
+
+func (s SqlRetentionPolicyStore) Save(policy *model.RetentionPolicy) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if len(policy.Id) > 0 {
+			result.Err = model.NewAppError("SqlRetentionPolicyStore.Save", "store.sql_retention_policy.save.existing.app_error", nil, "id="+policy.Id, http.StatusBadRequest)
+			return
+		}
+
+		policy.PreSave()
+		if result.Err = policy.IsValid(); result.Err != nil {
+			return
+		}
+
+		if err := s.GetMaster().Insert(policy); err != nil {
+			result.Err = model.NewAppError("SqlRetentionPolicyStore.Save", "store.sql_retention_policy.save.app_error", nil, "id="+policy.Id+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = policy
+		}
+	})
+}
+
+func (s SqlRetentionPolicyStore) Update(policy *model.RetentionPolicy) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		policy.PreUpdate()
+		if result.Err = policy.IsValid(); result.Err != nil {
+			return
+		}
+
+		if count, err := s.GetMaster().Update(policy); err != nil {
+			result.Err = model.NewAppError("SqlRetentionPolicyStore.Update", "store.sql_retention_policy.update.app_error", nil, "id="+policy.Id+", "+err.Error(), http.StatusInternalServerError)
+		} else if count != 1 {
+			result.Err = model.NewAppError("SqlRetentionPolicyStore.Update", "store.sql_retention_policy.get.missing.app_error", nil, "id="+policy.Id, http.StatusNotFound)
+		} else {
+			result.Data = policy
+		}
+	})
+}
+
+// loadAssignments fills in the channels and teams assigned to each of the policies.
+func (s SqlRetentionPolicyStore) loadAssignments(policies []*model.RetentionPolicy) *model.AppError {
+	if len(policies) == 0 {
+		return nil
+	}
+
+	byId := map[string]*model.RetentionPolicy{}
+	ids := make([]string, 0, len(policies))
+	for _, policy := range policies {
+		policy.ChannelIds = []string{}
+		policy.TeamIds = []string{}
+		byId[policy.Id] = policy
+		ids = append(ids, policy.Id)
+	}
+
+	props := map[string]interface{}{}
+	idQuery := buildIdListQuery("policyId", ids, props)
+
+	var channels []*model.RetentionPolicyChannel
+	if _, err := s.GetReplica().Select(&channels, "SELECT * FROM RetentionPoliciesChannels WHERE PolicyId IN ("+idQuery+") ORDER BY ChannelId", props); err != nil {
+		return model.NewAppError("SqlRetentionPolicyStore.loadAssignments", "store.sql_retention_policy.get.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	for _, channel := range channels {
+		byId[channel.PolicyId].ChannelIds = append(byId[channel.PolicyId].ChannelIds, channel.ChannelId)
+	}
+
+	var teams []*model.RetentionPolicyTeam
+	if _, err := s.GetReplica().Select(&teams, "SELECT * FROM RetentionPoliciesTeams WHERE PolicyId IN ("+idQuery+") ORDER BY TeamId", props); err != nil {
+		return model.NewAppError("SqlRetentionPolicyStore.loadAssignments", "store.sql_retention_policy.get.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	for _, team := range teams {
+		byId[team.PolicyId].TeamIds = append(byId[team.PolicyId].TeamIds, team.TeamId)
+	}
+
+	return nil
+}
+
+func (s SqlRetentionPolicyStore) Get(policyId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var policy model.RetentionPolicy
+		if err := s.GetReplica().SelectOne(&policy, "SELECT * FROM RetentionPolicies WHERE Id = :Id", map[string]interface{}{"Id": policyId}); err != nil {
+			if err == sql.ErrNoRows {
+				result.Err = model.NewAppError("SqlRetentionPolicyStore.Get", "store.sql_retention_policy.get.missing.app_error", nil, "id="+policyId, http.StatusNotFound)
+			} else {
+				result.Err = model.NewAppError("SqlRetentionPolicyStore.Get", "store.sql_retention_policy.get.app_error", nil, "id="+policyId+", "+err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		if result.Err = s.loadAssignments([]*model.RetentionPolicy{&policy}); result.Err != nil {
+			return
+		}
+
+		result.Data = &policy
+	})
+}
+
+func (s SqlRetentionPolicyStore) GetAll() store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		policies := []*model.RetentionPolicy{}
+		if _, err := s.GetReplica().Select(&policies, "SELECT * FROM RetentionPolicies ORDER BY DisplayName, Id"); err != nil {
+			result.Err = model.NewAppError("SqlRetentionPolicyStore.GetAll", "store.sql_retention_policy.get.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if result.Err = s.loadAssignments(policies); result.Err != nil {
+			return
+		}
+
+		result.Data = policies
+	})
+}
+
+func (s SqlRetentionPolicyStore) Delete(policyId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		transaction, err := s.GetMaster().Begin()
+		if err != nil {
+			result.Err = model.NewAppError("SqlRetentionPolicyStore.Delete", "store.sql_retention_policy.delete.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		props := map[string]interface{}{"PolicyId": policyId}
+
+		for _, query := range []string{
+			"DELETE FROM RetentionPoliciesChannels WHERE PolicyId = :PolicyId",
+			"DELETE FROM RetentionPoliciesTeams WHERE PolicyId = :PolicyId",
+			"DELETE FROM RetentionPolicies WHERE Id = :PolicyId",
+		} {
+			if _, err := transaction.Exec(query, props); err != nil {
+				transaction.Rollback()
+				result.Err = model.NewAppError("SqlRetentionPolicyStore.Delete", "store.sql_retention_policy.delete.app_error", nil, "id="+policyId+", "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlRetentionPolicyStore.Delete", "store.sql_retention_policy.delete.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// addAssignments inserts all of the assignments in a single transaction, so that none are added if any
+// of them is already assigned to a policy.
+func (s SqlRetentionPolicyStore) addAssignments(where string, policyId string, assignments []interface{}) *model.AppError {
+	transaction, err := s.GetMaster().Begin()
+	if err != nil {
+		return model.NewAppError(where, "store.sql_retention_policy.add_assignments.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	for _, assignment := range assignments {
+		if err := transaction.Insert(assignment); err != nil {
+			transaction.Rollback()
+			if IsUniqueConstraintError(err, []string{"ChannelId", "TeamId", "retentionpolicieschannels_pkey", "retentionpoliciesteams_pkey", "PRIMARY"}) {
+				return model.NewAppError(where, "store.sql_retention_policy.add_assignments.exists.app_error", nil, "policy_id="+policyId+", "+err.Error(), http.StatusBadRequest)
+			}
+			return model.NewAppError(where, "store.sql_retention_policy.add_assignments.app_error", nil, "policy_id="+policyId+", "+err.Error(), http.StatusInternalServerError)
+		}
+	}
+
+	if err := transaction.Commit(); err != nil {
+		return model.NewAppError(where, "store.sql_retention_policy.add_assignments.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+func (s SqlRetentionPolicyStore) AddChannels(policyId string, channelIds []string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		assignments := make([]interface{}, 0, len(channelIds))
+		for _, channelId := range channelIds {
+			assignments = append(assignments, &model.RetentionPolicyChannel{PolicyId: policyId, ChannelId: channelId})
+		}
+
+		result.Err = s.addAssignments("SqlRetentionPolicyStore.AddChannels", policyId, assignments)
+	})
+}
+
+func (s SqlRetentionPolicyStore) RemoveChannels(policyId string, channelIds []string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if len(channelIds) == 0 {
+			return
+		}
+
+		props := map[string]interface{}{"PolicyId": policyId}
+		query := "DELETE FROM RetentionPoliciesChannels WHERE PolicyId = :PolicyId AND ChannelId IN (" + buildIdListQuery("channelId", channelIds, props) + ")"
+
+		if _, err := s.GetMaster().Exec(query, props); err != nil {
+			result.Err = model.NewAppError("SqlRetentionPolicyStore.RemoveChannels", "store.sql_retention_policy.remove_assignments.app_error", nil, "policy_id="+policyId+", "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+func (s SqlRetentionPolicyStore) AddTeams(policyId string, teamIds []string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		assignments := make([]interface{}, 0, len(teamIds))
+		for _, teamId := range teamIds {
+			assignments = append(assignments, &model.RetentionPolicyTeam{PolicyId: policyId, TeamId: teamId})
+		}
+
+		result.Err = s.addAssignments("SqlRetentionPolicyStore.AddTeams", policyId, assignments)
+	})
+}
+
+func (s SqlRetentionPolicyStore) RemoveTeams(policyId string, teamIds []string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if len(teamIds) == 0 {
+			return
+		}
+
+		props := map[string]interface{}{"PolicyId": policyId}
+		query := "DELETE FROM RetentionPoliciesTeams WHERE PolicyId = :PolicyId AND TeamId IN (" + buildIdListQuery("teamId", teamIds, props) + ")"
+
+		if _, err := s.GetMaster().Exec(query, props); err != nil {
+			result.Err = model.NewAppError("SqlRetentionPolicyStore.RemoveTeams", "store.sql_retention_policy.remove_assignments.app_error", nil, "policy_id="+policyId+", "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// postsForDeletionQuery selects the posts created before :EndTime in the channels governed by a policy.
+func postsForDeletionQuery(selectClause string, policyId string, exemptPinned bool) string {
+	query := `
+		SELECT
+			` + selectClause + `
+		FROM
+			Posts,
+			Channels
+		WHERE
+			Posts.ChannelId = Channels.Id
+			AND Posts.CreateAt < :EndTime
+			AND ` + retentionScopeClause(policyId)
+
+	if exemptPinned {
+		query += `
+			AND Posts.IsPinned = false`
+	}
+
+	return query
+}
+
+// filesForDeletionQuery selects the files created before :EndTime that are attached to posts in the
+// channels governed by a policy, leaving out the files of pinned posts if they're exempt.
+func filesForDeletionQuery(selectClause string, policyId string, exemptPinned bool) string {
+	query := `
+		SELECT
+			` + selectClause + `
+		FROM
+			FileInfo,
+			Posts,
+			Channels
+		WHERE
+			FileInfo.PostId = Posts.Id
+			AND Posts.ChannelId = Channels.Id
+			AND FileInfo.CreateAt < :EndTime
+			AND ` + retentionScopeClause(policyId)
+
+	if exemptPinned {
+		query += `
+			AND Posts.IsPinned = false`
+	}
+
+	return query
+}
+
+func (s SqlRetentionPolicyStore) CountPostsForDeletion(policyId string, endTime int64, exemptPinned bool) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		query := postsForDeletionQuery("COUNT(*)", policyId, exemptPinned)
+
+		if count, err := s.GetReplica().SelectInt(query, map[string]interface{}{"PolicyId": policyId, "EndTime": endTime}); err != nil {
+			result.Err = model.NewAppError("SqlRetentionPolicyStore.CountPostsForDeletion", "store.sql_retention_policy.count_posts.app_error", nil, "policy_id="+policyId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = count
+		}
+	})
+}
+
+// PermanentDeletePostsBatch deletes up to limit posts created before endTime in the channels governed by a
+// policy, along with their reactions, files and threads. It returns the posts that were deleted.
+func (s SqlRetentionPolicyStore) PermanentDeletePostsBatch(policyId string, endTime int64, exemptPinned bool, limit int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var posts []*model.Post
+		query := postsForDeletionQuery("Posts.*", policyId, exemptPinned) + " LIMIT :Limit"
+
+		if _, err := s.GetMaster().Select(&posts, query, map[string]interface{}{"PolicyId": policyId, "EndTime": endTime, "Limit": limit}); err != nil {
+			result.Err = model.NewAppError("SqlRetentionPolicyStore.PermanentDeletePostsBatch", "store.sql_retention_policy.delete_posts.app_error", nil, "policy_id="+policyId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if len(posts) == 0 {
+			result.Data = posts
+			return
+		}
+
+		postIds := make([]string, len(posts))
+		for i, post := range posts {
+			postIds[i] = post.Id
+		}
+
+		transaction, err := s.GetMaster().Begin()
+		if err != nil {
+			result.Err = model.NewAppError("SqlRetentionPolicyStore.PermanentDeletePostsBatch", "store.sql_retention_policy.delete_posts.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		props := map[string]interface{}{}
+		idQuery := buildIdListQuery("postId", postIds, props)
+
+		for _, query := range []string{
+			"DELETE FROM Reactions WHERE PostId IN (" + idQuery + ")",
+			"DELETE FROM ThreadMemberships WHERE PostId IN (" + idQuery + ")",
+			"DELETE FROM Threads WHERE PostId IN (" + idQuery + ")",
+			"DELETE FROM FileInfo WHERE PostId IN (" + idQuery + ")",
+			"DELETE FROM Posts WHERE Id IN (" + idQuery + ")",
+		} {
+			if _, err := transaction.Exec(query, props); err != nil {
+				transaction.Rollback()
+				result.Err = model.NewAppError("SqlRetentionPolicyStore.PermanentDeletePostsBatch", "store.sql_retention_policy.delete_posts.app_error", nil, "policy_id="+policyId+", "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlRetentionPolicyStore.PermanentDeletePostsBatch", "store.sql_retention_policy.delete_posts.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = posts
+	})
+}
+
+func (s SqlRetentionPolicyStore) CountFilesForDeletion(policyId string, endTime int64, exemptPinned bool) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		query := filesForDeletionQuery("COUNT(*)", policyId, exemptPinned)
+
+		if count, err := s.GetReplica().SelectInt(query, map[string]interface{}{"PolicyId": policyId, "EndTime": endTime}); err != nil {
+			result.Err = model.NewAppError("SqlRetentionPolicyStore.CountFilesForDeletion", "store.sql_retention_policy.count_files.app_error", nil, "policy_id="+policyId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = count
+		}
+	})
+}
+
+// PermanentDeleteFilesBatch deletes up to limit file infos created before endTime that are attached to
+// posts in the channels governed by a policy, other than the files of pinned posts if exemptPinned is set. It
+// returns the number of file infos deleted.
+func (s SqlRetentionPolicyStore) PermanentDeleteFilesBatch(policyId string, endTime int64, exemptPinned bool, limit int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var fileIds []string
+		query := filesForDeletionQuery("FileInfo.Id", policyId, exemptPinned) + " LIMIT :Limit"
+
+		if _, err := s.GetMaster().Select(&fileIds, query, map[string]interface{}{"PolicyId": policyId, "EndTime": endTime, "Limit": limit}); err != nil {
+			result.Err = model.NewAppError("SqlRetentionPolicyStore.PermanentDeleteFilesBatch", "store.sql_retention_policy.delete_files.app_error", nil, "policy_id="+policyId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if len(fileIds) == 0 {
+			result.Data = int64(0)
+			return
+		}
+
+		props := map[string]interface{}{}
+		if _, err := s.GetMaster().Exec("DELETE FROM FileInfo WHERE Id IN ("+buildIdListQuery("fileId", fileIds, props)+")", props); err != nil {
+			result.Err = model.NewAppError("SqlRetentionPolicyStore.PermanentDeleteFilesBatch", "store.sql_retention_policy.delete_files.app_error", nil, "policy_id="+policyId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = int64(len(fileIds))
+	})
+}
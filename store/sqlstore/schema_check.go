@@ -0,0 +1,476 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/mattermost/gorp"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+const (
+	SCHEMA_ISSUE_MISSING_TABLE  = "missing_table"
+	SCHEMA_ISSUE_MISSING_COLUMN = "missing_column"
+	SCHEMA_ISSUE_EXTRA_COLUMN   = "extra_column"
+	SCHEMA_ISSUE_MISSING_INDEX  = "missing_index"
+	SCHEMA_ISSUE_INVALID_INDEX  = "invalid_index"
+	SCHEMA_ISSUE_EXTRA_INDEX    = "extra_index"
+	SCHEMA_ISSUE_CHARSET        = "charset"
+
+	// SCHEMA_MANAGED_INDEX_PREFIX starts the names of the indexes that the stores create. Other indexes come from the
+	// keys and unique columns of tables, so they aren't reported as extra.
+	SCHEMA_MANAGED_INDEX_PREFIX = "idx_"
+
+	SCHEMA_EXPECTED_MYSQL_CHARSET    = "utf8mb4"
+	SCHEMA_EXPECTED_POSTGRES_CHARSET = "UTF8"
+)
+
+// schemaTableTypes are the types of every table that the stores map. It needs to be kept in sync with the calls to
+// AddTableWithName so that CheckSchema can find the table maps.
+var schemaTableTypes = []interface{}{
+	model.AccessData{},
+	model.AnalyticsDaily{},
+	model.AnalyticsUserDaily{},
+	model.Audit{},
+	model.AuthData{},
+	model.BlockedDomain{},
+	model.Channel{},
+	model.ChannelMember{},
+	model.ChannelMemberHistory{},
+	model.ChannelNameHistory{},
+	model.ClusterDiscovery{},
+	model.Command{},
+	model.CommandWebhook{},
+	model.Compliance{},
+	model.ConfigurationHistory{},
+	model.CustomGroup{},
+	model.Emoji{},
+	model.EmojiStats{},
+	model.FileInfo{},
+	model.FilePublicLink{},
+	model.FileStorageUsage{},
+	model.GroupMember{},
+	model.IncomingWebhook{},
+	model.InviteLink{},
+	model.InviteLinkRedemption{},
+	model.Job{},
+	model.LicenseRecord{},
+	model.OAuthApp{},
+	model.OutgoingWebhook{},
+	model.PluginKeyValue{},
+	model.Post{},
+	model.PostHistory{},
+	model.Preference{},
+	model.ProvisioningToken{},
+	model.Reaction{},
+	model.RetentionPolicy{},
+	model.RetentionPolicyChannel{},
+	model.RetentionPolicyTeam{},
+	model.Session{},
+	model.ShortPermalink{},
+	model.SidebarCategory{},
+	model.SidebarChannel{},
+	model.Status{},
+	model.System{},
+	model.Team{},
+	model.TeamInvitation{},
+	model.TeamMember{},
+	model.TeamTemplate{},
+	model.TermsOfService{},
+	model.Thread{},
+	model.ThreadMembership{},
+	model.Token{},
+	model.User{},
+	model.UserAccessToken{},
+	model.UserAttribute{},
+	model.UserAttributeField{},
+	model.UserTermsOfService{},
+	model.UsernameHistory{},
+	model.WebSocketOutboxEvent{},
+	model.PostIdempotencyKey{},
+	Role{},
+	channelModeration{},
+	teamDefaultChannel{},
+	teamFilteredWord{},
+}
+
+// SchemaIssue is a difference between the schema of the database and the one that the stores expect.
+type SchemaIssue struct {
+	Kind   string
+	Table  string
+	Name   string
+	Detail string
+
+	// Fixable is true if RepairSchema can fix the issue by adding to the schema without changing or removing
+	// anything that's in it.
+	Fixable bool
+
+	table *gorp.TableMap
+	index *schemaIndex
+}
+
+func (i *SchemaIssue) String() string {
+	description := i.Kind + " " + i.Table
+	if i.Name != "" {
+		description += "." + i.Name
+	}
+
+	if i.Detail != "" {
+		description += " (" + i.Detail + ")"
+	}
+
+	return description
+}
+
+type schemaIndex struct {
+	name      string
+	table     string
+	columns   []string
+	indexType string
+	unique    bool
+}
+
+// schemaIndexPlan collects the indexes that the stores would create and remove instead of changing the database.
+type schemaIndexPlan struct {
+	created []*schemaIndex
+	removed []*schemaIndex
+}
+
+type liveSchemaTable struct {
+	columns      []string
+	hasColumn    map[string]bool
+	collation    string
+	wrongCharset []string
+	indexes      []string
+	validIndex   map[string]bool
+}
+
+type liveSchema struct {
+	tables  map[string]*liveSchemaTable
+	charset string
+}
+
+// expectedTables returns the table maps of every table that the stores use.
+func (ss *SqlSupplier) expectedTables() ([]*gorp.TableMap, error) {
+	tables := make([]*gorp.TableMap, 0, len(schemaTableTypes))
+	for _, tableType := range schemaTableTypes {
+		table, err := ss.GetMaster().TableFor(reflect.TypeOf(tableType), false)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+
+	return tables, nil
+}
+
+// expectedIndexes returns the indexes that the stores create and remove when the server starts. The database isn't
+// changed while they're collected, so this mustn't be called while another goroutine could be creating indexes.
+func (ss *SqlSupplier) expectedIndexes() *schemaIndexPlan {
+	ss.indexPlan = &schemaIndexPlan{}
+	defer func() {
+		ss.indexPlan = nil
+	}()
+
+	ss.createIndexesIfNotExists()
+
+	return ss.indexPlan
+}
+
+func (ss *SqlSupplier) readLiveSchema() (*liveSchema, error) {
+	schema := &liveSchema{tables: map[string]*liveSchemaTable{}}
+
+	var tables []struct {
+		TableName string
+		Collation string
+	}
+	var columns []struct {
+		TableName  string
+		ColumnName string
+		Charset    string
+	}
+	var indexes []struct {
+		TableName string
+		IndexName string
+		Valid     bool
+	}
+
+	if ss.DriverName() == model.DATABASE_DRIVER_POSTGRES {
+		if _, err := ss.GetMaster().Select(&tables, "SELECT table_name AS TableName, '' AS Collation FROM information_schema.tables WHERE table_schema = current_schema() AND table_type = 'BASE TABLE'"); err != nil {
+			return nil, err
+		}
+
+		if _, err := ss.GetMaster().Select(&columns, "SELECT table_name AS TableName, column_name AS ColumnName, '' AS Charset FROM information_schema.columns WHERE table_schema = current_schema() ORDER BY table_name, ordinal_position"); err != nil {
+			return nil, err
+		}
+
+		if _, err := ss.GetMaster().Select(&indexes, `
+			SELECT
+				t.relname AS TableName,
+				i.relname AS IndexName,
+				x.indisvalid AS Valid
+			FROM
+				pg_index x
+				JOIN pg_class i ON i.oid = x.indexrelid
+				JOIN pg_class t ON t.oid = x.indrelid
+				JOIN pg_namespace n ON n.oid = t.relnamespace
+			WHERE
+				n.nspname = current_schema()
+			ORDER BY
+				t.relname, i.relname`); err != nil {
+			return nil, err
+		}
+
+		charset, err := ss.GetMaster().SelectStr("SELECT pg_encoding_to_char(encoding) FROM pg_database WHERE datname = current_database()")
+		if err != nil {
+			return nil, err
+		}
+		schema.charset = charset
+	} else if ss.DriverName() == model.DATABASE_DRIVER_MYSQL {
+		if _, err := ss.GetMaster().Select(&tables, "SELECT TABLE_NAME AS TableName, COALESCE(TABLE_COLLATION, '') AS Collation FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE'"); err != nil {
+			return nil, err
+		}
+
+		if _, err := ss.GetMaster().Select(&columns, "SELECT TABLE_NAME AS TableName, COLUMN_NAME AS ColumnName, COALESCE(CHARACTER_SET_NAME, '') AS Charset FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() ORDER BY TABLE_NAME, ORDINAL_POSITION"); err != nil {
+			return nil, err
+		}
+
+		if _, err := ss.GetMaster().Select(&indexes, "SELECT DISTINCT TABLE_NAME AS TableName, INDEX_NAME AS IndexName, 1 AS Valid FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = DATABASE() ORDER BY TABLE_NAME, INDEX_NAME"); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("checking the schema isn't supported for %v", ss.DriverName())
+	}
+
+	for _, table := range tables {
+		schema.tables[strings.ToLower(table.TableName)] = &liveSchemaTable{
+			hasColumn:  map[string]bool{},
+			collation:  table.Collation,
+			validIndex: map[string]bool{},
+		}
+	}
+
+	for _, column := range columns {
+		table := schema.tables[strings.ToLower(column.TableName)]
+		if table == nil {
+			continue
+		}
+
+		table.columns = append(table.columns, column.ColumnName)
+		table.hasColumn[strings.ToLower(column.ColumnName)] = true
+		if column.Charset != "" && !strings.EqualFold(column.Charset, SCHEMA_EXPECTED_MYSQL_CHARSET) {
+			table.wrongCharset = append(table.wrongCharset, column.ColumnName+" uses "+column.Charset)
+		}
+	}
+
+	for _, index := range indexes {
+		table := schema.tables[strings.ToLower(index.TableName)]
+		if table == nil {
+			continue
+		}
+
+		table.indexes = append(table.indexes, index.IndexName)
+		table.validIndex[strings.ToLower(index.IndexName)] = index.Valid
+	}
+
+	return schema, nil
+}
+
+// CheckSchema compares the tables, columns and indexes in the database against the ones that the stores expect, and
+// returns every difference between them. Nothing in the database is changed.
+func (ss *SqlSupplier) CheckSchema() ([]*SchemaIssue, error) {
+	live, err := ss.readLiveSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	tables, err := ss.expectedTables()
+	if err != nil {
+		return nil, err
+	}
+
+	issues := []*SchemaIssue{}
+
+	if live.charset != "" && !strings.EqualFold(live.charset, SCHEMA_EXPECTED_POSTGRES_CHARSET) {
+		issues = append(issues, &SchemaIssue{
+			Kind:   SCHEMA_ISSUE_CHARSET,
+			Detail: fmt.Sprintf("the database uses %v instead of %v", live.charset, SCHEMA_EXPECTED_POSTGRES_CHARSET),
+		})
+	}
+
+	for _, table := range tables {
+		liveTable := live.tables[strings.ToLower(table.TableName)]
+		if liveTable == nil {
+			issues = append(issues, &SchemaIssue{
+				Kind:    SCHEMA_ISSUE_MISSING_TABLE,
+				Table:   table.TableName,
+				Fixable: true,
+				table:   table,
+			})
+			continue
+		}
+
+		expectedColumns := map[string]bool{}
+		for _, column := range table.Columns {
+			if column.Transient {
+				continue
+			}
+
+			expectedColumns[strings.ToLower(column.ColumnName)] = true
+			if !liveTable.hasColumn[strings.ToLower(column.ColumnName)] {
+				issues = append(issues, &SchemaIssue{
+					Kind:   SCHEMA_ISSUE_MISSING_COLUMN,
+					Table:  table.TableName,
+					Name:   column.ColumnName,
+					Detail: "it's added by upgrading the database",
+				})
+			}
+		}
+
+		for _, column := range liveTable.columns {
+			if !expectedColumns[strings.ToLower(column)] {
+				issues = append(issues, &SchemaIssue{
+					Kind:  SCHEMA_ISSUE_EXTRA_COLUMN,
+					Table: table.TableName,
+					Name:  column,
+				})
+			}
+		}
+
+		if liveTable.collation != "" && !strings.HasPrefix(strings.ToLower(liveTable.collation), SCHEMA_EXPECTED_MYSQL_CHARSET+"_") {
+			issues = append(issues, &SchemaIssue{
+				Kind:   SCHEMA_ISSUE_CHARSET,
+				Table:  table.TableName,
+				Detail: fmt.Sprintf("the table uses the %v collation instead of a %v one", liveTable.collation, SCHEMA_EXPECTED_MYSQL_CHARSET),
+			})
+		}
+
+		for _, column := range liveTable.wrongCharset {
+			issues = append(issues, &SchemaIssue{
+				Kind:   SCHEMA_ISSUE_CHARSET,
+				Table:  table.TableName,
+				Detail: fmt.Sprintf("%v instead of %v", column, SCHEMA_EXPECTED_MYSQL_CHARSET),
+			})
+		}
+	}
+
+	plan := ss.expectedIndexes()
+
+	expectedIndexes := map[string]bool{}
+	for _, index := range plan.created {
+		expectedIndexes[strings.ToLower(index.name)] = true
+
+		liveTable := live.tables[strings.ToLower(index.table)]
+		if liveTable == nil {
+			issues = append(issues, &SchemaIssue{Kind: SCHEMA_ISSUE_MISSING_INDEX, Table: index.table, Name: index.name, Fixable: true, index: index})
+		} else if valid, ok := liveTable.validIndex[strings.ToLower(index.name)]; !ok {
+			issues = append(issues, &SchemaIssue{Kind: SCHEMA_ISSUE_MISSING_INDEX, Table: index.table, Name: index.name, Fixable: true, index: index})
+		} else if !valid {
+			issues = append(issues, &SchemaIssue{
+				Kind:    SCHEMA_ISSUE_INVALID_INDEX,
+				Table:   index.table,
+				Name:    index.name,
+				Detail:  "building it didn't finish",
+				Fixable: true,
+				index:   index,
+			})
+		}
+	}
+
+	for _, index := range plan.removed {
+		if liveTable := live.tables[strings.ToLower(index.table)]; liveTable != nil {
+			if _, ok := liveTable.validIndex[strings.ToLower(index.name)]; ok && !expectedIndexes[strings.ToLower(index.name)] {
+				issues = append(issues, &SchemaIssue{Kind: SCHEMA_ISSUE_EXTRA_INDEX, Table: index.table, Name: index.name, Detail: "it's no longer used"})
+				expectedIndexes[strings.ToLower(index.name)] = true
+			}
+		}
+	}
+
+	for _, table := range tables {
+		liveTable := live.tables[strings.ToLower(table.TableName)]
+		if liveTable == nil {
+			continue
+		}
+
+		for _, index := range liveTable.indexes {
+			if strings.HasPrefix(strings.ToLower(index), SCHEMA_MANAGED_INDEX_PREFIX) && !expectedIndexes[strings.ToLower(index)] {
+				issues = append(issues, &SchemaIssue{Kind: SCHEMA_ISSUE_EXTRA_INDEX, Table: table.TableName, Name: index})
+			}
+		}
+	}
+
+	return issues, nil
+}
+
+// RepairSchema fixes the issues from CheckSchema that can be fixed by adding to the schema, and returns the ones that
+// it fixed. Missing tables are created, and missing indexes are built without locking their tables against writes
+// where the database supports it. Issues that can't be fixed safely are skipped.
+func (ss *SqlSupplier) RepairSchema(issues []*SchemaIssue) ([]*SchemaIssue, error) {
+	fixed := []*SchemaIssue{}
+
+	// Tables are created first so that their indexes can be built
+	for _, issue := range issues {
+		if issue.Fixable && issue.Kind == SCHEMA_ISSUE_MISSING_TABLE {
+			if _, err := ss.GetMaster().ExecNoTimeout(issue.table.SqlForCreate(true)); err != nil {
+				return fixed, fmt.Errorf("unable to create table %v: %v", issue.Table, err)
+			}
+			fixed = append(fixed, issue)
+		}
+	}
+
+	for _, issue := range issues {
+		if !issue.Fixable || (issue.Kind != SCHEMA_ISSUE_MISSING_INDEX && issue.Kind != SCHEMA_ISSUE_INVALID_INDEX) {
+			continue
+		}
+
+		if issue.Kind == SCHEMA_ISSUE_INVALID_INDEX {
+			// Postgres leaves behind an index that can't be used when building it concurrently fails, and it has to
+			// be dropped before it can be built again
+			if _, err := ss.GetMaster().ExecNoTimeout("DROP INDEX CONCURRENTLY IF EXISTS " + issue.index.name); err != nil {
+				return fixed, fmt.Errorf("unable to drop invalid index %v: %v", issue.Name, err)
+			}
+		}
+
+		if _, err := ss.GetMaster().ExecNoTimeout(ss.createIndexQuery(issue.index, true)); err != nil {
+			return fixed, fmt.Errorf("unable to create index %v: %v", issue.Name, err)
+		}
+		fixed = append(fixed, issue)
+	}
+
+	return fixed, nil
+}
+
+// CheckSchemaIsCurrent returns an error if the database in settings hasn't been migrated to the schema version that
+// this server needs, or is missing any of the tables, columns or indexes that it uses. Nothing is changed in the
+// database.
+func CheckSchemaIsCurrent(settings model.SqlSettings) error {
+	supplier := NewSqlSupplierForSchemaCheck(settings)
+	defer supplier.Close()
+
+	if version := supplier.GetCurrentSchemaVersion(); version != model.CurrentVersion {
+		return fmt.Errorf("the database schema version is %q instead of %v", version, model.CurrentVersion)
+	}
+
+	issues, err := supplier.CheckSchema()
+	if err != nil {
+		return err
+	}
+
+	missing := []string{}
+	for _, issue := range issues {
+		if issue.Kind == SCHEMA_ISSUE_MISSING_TABLE || issue.Kind == SCHEMA_ISSUE_MISSING_COLUMN || issue.Kind == SCHEMA_ISSUE_MISSING_INDEX {
+			missing = append(missing, issue.String())
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("the database schema is out of date: %v", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
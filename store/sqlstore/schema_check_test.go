@@ -0,0 +1,67 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSchema(t *testing.T) {
+	findIssue := func(issues []*SchemaIssue, kind string, name string) *SchemaIssue {
+		for _, issue := range issues {
+			if issue.Kind == kind && issue.Name == name {
+				return issue
+			}
+		}
+		return nil
+	}
+
+	for _, st := range storeTypes {
+		st := st
+		t.Run(st.Name, func(t *testing.T) {
+			supplier := NewSqlSupplierForSchemaCheck(*st.Settings)
+			defer supplier.Close()
+
+			tables := reflect.ValueOf(supplier.GetMaster()).Elem().FieldByName("tables")
+			require.Len(t, schemaTableTypes, tables.Len(), "every mapped table should be checked")
+
+			issues, err := supplier.CheckSchema()
+			require.Nil(t, err)
+			for _, issue := range issues {
+				assert.NotContains(t, []string{SCHEMA_ISSUE_MISSING_TABLE, SCHEMA_ISSUE_MISSING_COLUMN, SCHEMA_ISSUE_MISSING_INDEX}, issue.Kind, issue.String())
+			}
+
+			supplier.RemoveIndexIfExists("idx_posts_channel_id_update_at", "Posts")
+			supplier.CreateIndexIfNotExists("idx_posts_schema_check", "Posts", "OriginalId")
+			defer supplier.RemoveIndexIfExists("idx_posts_schema_check", "Posts")
+
+			issues, err = supplier.CheckSchema()
+			require.Nil(t, err)
+
+			missing := findIssue(issues, SCHEMA_ISSUE_MISSING_INDEX, "idx_posts_channel_id_update_at")
+			require.NotNil(t, missing, "should find the dropped index")
+			assert.Equal(t, "Posts", missing.Table)
+			assert.True(t, missing.Fixable)
+
+			extra := findIssue(issues, SCHEMA_ISSUE_EXTRA_INDEX, "idx_posts_schema_check")
+			require.NotNil(t, extra, "should find the index that isn't expected")
+			assert.False(t, extra.Fixable, "removing indexes shouldn't be done automatically")
+
+			fixed, err := supplier.RepairSchema(issues)
+			require.Nil(t, err)
+			assert.Contains(t, fixed, missing)
+			assert.NotContains(t, fixed, extra)
+
+			issues, err = supplier.CheckSchema()
+			require.Nil(t, err)
+			assert.Nil(t, findIssue(issues, SCHEMA_ISSUE_MISSING_INDEX, "idx_posts_channel_id_update_at"), "should have created the index")
+			assert.NotNil(t, findIssue(issues, SCHEMA_ISSUE_EXTRA_INDEX, "idx_posts_schema_check"), "should have left the extra index")
+			assert.Nil(t, supplier.indexPlan)
+		})
+	}
+}
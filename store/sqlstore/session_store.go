@@ -6,17 +6,13 @@ package sqlstore
 import (
 	"fmt"
 	"net/http"
-	"time"
+	"strconv"
+	"strings"
 
-	"github.com/mattermost/mattermost-server/mlog"
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/store"
 )
 
-const (
-	SESSIONS_CLEANUP_DELAY_MILLISECONDS = 100
-)
-
 type SqlSessionStore struct {
 	SqlStore
 }
@@ -186,6 +182,32 @@ func (me SqlSessionStore) UpdateLastActivityAt(sessionId string, time int64) sto
 	})
 }
 
+// UpdateLastActivityAtBatch sets the last activity of many sessions, keyed by their ids, in a single statement.
+func (me SqlSessionStore) UpdateLastActivityAtBatch(lastActivityAt map[string]int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if len(lastActivityAt) == 0 {
+			return
+		}
+
+		params := make(map[string]interface{}, len(lastActivityAt))
+		cases := ""
+		ids := make([]string, 0, len(lastActivityAt))
+		for sessionId, time := range lastActivityAt {
+			key := "Id" + strconv.Itoa(len(ids))
+			params[key] = sessionId
+
+			// The times are inlined so that the database doesn't have to infer the type of the CASE expression
+			cases += fmt.Sprintf(" WHEN :%v THEN %d", key, time)
+			ids = append(ids, ":"+key)
+		}
+
+		query := "UPDATE Sessions SET LastActivityAt = CASE Id" + cases + " END WHERE Id IN (" + strings.Join(ids, ", ") + ")"
+		if _, err := me.GetMaster().Exec(query, params); err != nil {
+			result.Err = model.NewAppError("SqlSessionStore.UpdateLastActivityAtBatch", "store.sql_session.update_last_activity.app_error", nil, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
 func (me SqlSessionStore) UpdateRoles(userId, roles string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		if _, err := me.GetMaster().Exec("UPDATE Sessions SET Roles = :Roles WHERE UserId = :UserId", map[string]interface{}{"Roles": roles, "UserId": userId}); err != nil {
@@ -206,6 +228,32 @@ func (me SqlSessionStore) UpdateDeviceId(id string, deviceId string, expiresAt i
 	})
 }
 
+func (me SqlSessionStore) UpdateProps(sessionId string, props model.StringMap) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if _, err := me.GetMaster().Exec("UPDATE Sessions SET Props = :Props WHERE Id = :Id", map[string]interface{}{"Props": model.MapToJson(props), "Id": sessionId}); err != nil {
+			result.Err = model.NewAppError("SqlSessionStore.UpdateProps", "store.sql_session.update_props.app_error", nil, "session_id="+sessionId+", "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// GetImpersonationSessions returns the sessions that were started from the given session to impersonate
+// other users.
+func (me SqlSessionStore) GetImpersonationSessions(impersonatorSessionId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var sessions []*model.Session
+
+		// Props are stored as JSON with sorted keys and no whitespace, so the impersonator's session can be
+		// matched exactly.
+		props := "%\"" + model.SESSION_PROP_IMPERSONATOR_SESSION_ID + "\":\"" + impersonatorSessionId + "\"%"
+
+		if _, err := me.GetReplica().Select(&sessions, "SELECT * FROM Sessions WHERE IsImpersonation = :IsImpersonation AND Props LIKE :Props", map[string]interface{}{"IsImpersonation": true, "Props": props}); err != nil {
+			result.Err = model.NewAppError("SqlSessionStore.GetImpersonationSessions", "store.sql_session.get_impersonation_sessions.app_error", nil, "session_id="+impersonatorSessionId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = sessions
+		}
+	})
+}
+
 func (me SqlSessionStore) AnalyticsSessionCount() store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		query :=
@@ -223,31 +271,65 @@ func (me SqlSessionStore) AnalyticsSessionCount() store.StoreChannel {
 	})
 }
 
-func (me SqlSessionStore) Cleanup(expiryTime int64, batchSize int64) {
-	mlog.Debug("Cleaning up session store.")
+// PermanentDeleteExpiredBatch deletes up to limit sessions that expired before the given time and returns them.
+func (me SqlSessionStore) PermanentDeleteExpiredBatch(expiredBefore int64, limit int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var sessions []*model.Session
+		if _, err := me.GetMaster().Select(&sessions, "SELECT * FROM Sessions WHERE ExpiresAt != 0 AND ExpiresAt < :ExpiredBefore LIMIT :Limit", map[string]interface{}{"ExpiredBefore": expiredBefore, "Limit": limit}); err != nil {
+			result.Err = model.NewAppError("SqlSessionStore.PermanentDeleteExpiredBatch", "store.sql_session.permanent_delete_batch.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
 
-	var query string
-	if me.DriverName() == model.DATABASE_DRIVER_POSTGRES {
-		query = "DELETE FROM Sessions WHERE Id = any (array (SELECT Id FROM Sessions WHERE ExpiresAt != 0 AND :ExpiresAt > ExpiresAt LIMIT :Limit))"
-	} else {
-		query = "DELETE FROM Sessions WHERE ExpiresAt != 0 AND :ExpiresAt > ExpiresAt LIMIT :Limit"
-	}
+		if err := me.permanentDeleteSessions(sessions); err != nil {
+			result.Err = model.NewAppError("SqlSessionStore.PermanentDeleteExpiredBatch", "store.sql_session.permanent_delete_batch.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
 
-	var rowsAffected int64 = 1
+		result.Data = sessions
+	})
+}
 
-	for rowsAffected > 0 {
-		if sqlResult, err := me.GetMaster().Exec(query, map[string]interface{}{"ExpiresAt": expiryTime, "Limit": batchSize}); err != nil {
-			mlog.Error(fmt.Sprintf("Unable to cleanup session store. err=%v", err.Error()))
+// PermanentDeleteOrphanedBatch deletes up to limit sessions of users that have been deleted or deactivated and returns
+// them.
+func (me SqlSessionStore) PermanentDeleteOrphanedBatch(limit int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var sessions []*model.Session
+		if _, err := me.GetMaster().Select(&sessions, `
+			SELECT
+				Sessions.*
+			FROM
+				Sessions
+				LEFT JOIN Users ON Sessions.UserId = Users.Id
+			WHERE
+				Users.Id IS NULL
+				OR Users.DeleteAt != 0
+			LIMIT :Limit`, map[string]interface{}{"Limit": limit}); err != nil {
+			result.Err = model.NewAppError("SqlSessionStore.PermanentDeleteOrphanedBatch", "store.sql_session.permanent_delete_batch.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := me.permanentDeleteSessions(sessions); err != nil {
+			result.Err = model.NewAppError("SqlSessionStore.PermanentDeleteOrphanedBatch", "store.sql_session.permanent_delete_batch.app_error", nil, err.Error(), http.StatusInternalServerError)
 			return
-		} else {
-			var rowErr error
-			rowsAffected, rowErr = sqlResult.RowsAffected()
-			if rowErr != nil {
-				mlog.Error(fmt.Sprintf("Unable to cleanup session store. err=%v", err.Error()))
-				return
-			}
 		}
 
-		time.Sleep(SESSIONS_CLEANUP_DELAY_MILLISECONDS * time.Millisecond)
+		result.Data = sessions
+	})
+}
+
+func (me SqlSessionStore) permanentDeleteSessions(sessions []*model.Session) error {
+	if len(sessions) == 0 {
+		return nil
 	}
+
+	params := make(map[string]interface{}, len(sessions))
+	ids := make([]string, len(sessions))
+	for i, session := range sessions {
+		key := "Id" + strconv.Itoa(i)
+		params[key] = session.Id
+		ids[i] = ":" + key
+	}
+
+	_, err := me.GetMaster().Exec("DELETE FROM Sessions WHERE Id IN ("+strings.Join(ids, ", ")+")", params)
+	return err
 }
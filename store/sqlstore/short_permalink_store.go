@@ -0,0 +1,106 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+type SqlShortPermalinkStore struct {
+	SqlStore
+}
+
+func NewSqlShortPermalinkStore(sqlStore SqlStore) store.ShortPermalinkStore {
+	s := &SqlShortPermalinkStore{sqlStore}
+
+	for _, db := range sqlStore.GetAllConns() {
+		table := db.AddTableWithName(model.ShortPermalink{}, "ShortPermalinks").SetKeys(false, "Code")
+		table.ColMap("Code").SetMaxSize(model.SHORT_PERMALINK_CODE_LENGTH)
+		table.ColMap("PostId").SetMaxSize(26)
+		table.ColMap("TeamId").SetMaxSize(26)
+	}
+
+	return s
+}
+
+func (s SqlShortPermalinkStore) CreateIndexesIfNotExists() {
+	s.CreateIndexIfNotExists("idx_short_permalinks_post_id", "ShortPermalinks", "PostId")
+	s.CreateIndexIfNotExists("idx_short_permalinks_expire_at", "ShortPermalinks", "ExpireAt")
+}
+
+func (s SqlShortPermalinkStore) Save(link *model.ShortPermalink) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		link.PreSave()
+
+		if result.Err = link.IsValid(); result.Err != nil {
+			return
+		}
+
+		if err := s.GetMaster().Insert(link); err != nil {
+			result.Err = model.NewAppError("SqlShortPermalinkStore.Save", "store.sql_short_permalink.save.app_error", nil, "post_id="+link.PostId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = link
+		}
+	})
+}
+
+// Get returns the short permalink with the given code, whether or not it's expired.
+func (s SqlShortPermalinkStore) Get(code string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		link := model.ShortPermalink{}
+
+		// Links are followed right after the notification with them is sent, so they're read from the master
+		if err := s.GetMaster().SelectOne(&link, "SELECT * FROM ShortPermalinks WHERE Code = :Code", map[string]interface{}{"Code": code}); err != nil {
+			if err == sql.ErrNoRows {
+				result.Err = model.NewAppError("SqlShortPermalinkStore.Get", "store.sql_short_permalink.get.app_error", nil, err.Error(), http.StatusNotFound)
+			} else {
+				result.Err = model.NewAppError("SqlShortPermalinkStore.Get", "store.sql_short_permalink.get.app_error", nil, err.Error(), http.StatusInternalServerError)
+			}
+		} else {
+			result.Data = &link
+		}
+	})
+}
+
+// GetLatestForPost returns the short permalink to the post on the team that expires last, as long as it expires
+// after the given time.
+func (s SqlShortPermalinkStore) GetLatestForPost(postId string, teamId string, expireAfter int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var links []*model.ShortPermalink
+
+		if _, err := s.GetMaster().Select(&links,
+			"SELECT * FROM ShortPermalinks WHERE PostId = :PostId AND TeamId = :TeamId AND ExpireAt > :ExpireAfter ORDER BY ExpireAt DESC LIMIT 1",
+			map[string]interface{}{"PostId": postId, "TeamId": teamId, "ExpireAfter": expireAfter}); err != nil {
+			result.Err = model.NewAppError("SqlShortPermalinkStore.GetLatestForPost", "store.sql_short_permalink.get_latest_for_post.app_error", nil, "post_id="+postId+", "+err.Error(), http.StatusInternalServerError)
+		} else if len(links) == 0 {
+			result.Err = model.NewAppError("SqlShortPermalinkStore.GetLatestForPost", "store.sql_short_permalink.get_latest_for_post.app_error", nil, "post_id="+postId, http.StatusNotFound)
+		} else {
+			result.Data = links[0]
+		}
+	})
+}
+
+// PermanentDeleteExpired deletes the short permalinks that expired before the given time, returning how many were
+// deleted.
+func (s SqlShortPermalinkStore) PermanentDeleteExpired(expiredBefore int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		sqlResult, err := s.GetMaster().Exec("DELETE FROM ShortPermalinks WHERE ExpireAt < :ExpiredBefore", map[string]interface{}{"ExpiredBefore": expiredBefore})
+		if err != nil {
+			result.Err = model.NewAppError("SqlShortPermalinkStore.PermanentDeleteExpired", "store.sql_short_permalink.permanent_delete_expired.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rowsAffected, err := sqlResult.RowsAffected()
+		if err != nil {
+			result.Err = model.NewAppError("SqlShortPermalinkStore.PermanentDeleteExpired", "store.sql_short_permalink.permanent_delete_expired.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = rowsAffected
+	})
+}
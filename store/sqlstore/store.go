@@ -9,6 +9,7 @@ import (
 	"github.com/mattermost/gorp"
 
 	"github.com/mattermost/mattermost-server/store"
+	"github.com/mattermost/mattermost-server/utils"
 )
 
 /*type SqlStore struct {
@@ -45,9 +46,12 @@ type SqlStore interface {
 	GetMaster() *gorp.DbMap
 	GetSearchReplica() *gorp.DbMap
 	GetReplica() *gorp.DbMap
+	GetCacheProvider() *utils.CacheProvider
 	TotalMasterDbConnections() int
 	TotalReadDbConnections() int
 	TotalSearchDbConnections() int
+	IsMasterHealthy() bool
+	IsFullUnicodeSupported() bool
 	MarkSystemRanUnitTests()
 	DoesTableExist(tablename string) bool
 	DoesColumnExist(tableName string, columName string) bool
@@ -19,6 +19,7 @@ var storeTypes = []*struct {
 	Name      string
 	Func      func() (*storetest.RunningContainer, *model.SqlSettings, error)
 	Container *storetest.RunningContainer
+	Settings  *model.SqlSettings
 	Store     store.Store
 }{
 	{
@@ -64,7 +65,8 @@ func initStores() {
 				return
 			}
 			st.Container = container
-			st.Store = store.NewLayeredStore(NewSqlSupplier(*settings, nil), nil, nil)
+			st.Settings = settings
+			st.Store = store.NewLayeredStore(NewSqlSupplier(*settings, nil, nil), nil, nil)
 			st.Store.MarkSystemRanUnitTests()
 		}()
 	}
@@ -12,6 +12,7 @@ import (
 	sqltrace "log"
 	"os"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -63,6 +64,7 @@ const (
 	EXIT_REMOVE_INDEX_SQLITE         = 136
 	EXIT_TABLE_EXISTS_SQLITE         = 137
 	EXIT_DOES_COLUMN_EXISTS_SQLITE   = 138
+	EXIT_MIGRATION_LOCK              = 139
 )
 
 type SqlSupplierOldStores struct {
@@ -90,6 +92,23 @@ type SqlSupplierOldStores struct {
 	userAccessToken      store.UserAccessTokenStore
 	plugin               store.PluginStore
 	channelMemberHistory store.ChannelMemberHistoryStore
+	thread               store.ThreadStore
+	customGroup          store.CustomGroupStore
+	userAttribute        store.UserAttributeStore
+	retentionPolicy      store.RetentionPolicyStore
+	termsOfService       store.TermsOfServiceStore
+	analyticsDaily       store.AnalyticsDailyStore
+	provisioningToken    store.ProvisioningTokenStore
+	teamTemplate         store.TeamTemplateStore
+	inviteLink           store.InviteLinkStore
+	teamInvitation       store.TeamInvitationStore
+	shortPermalink       store.ShortPermalinkStore
+	configurationHistory store.ConfigurationHistoryStore
+	filePublicLink       store.FilePublicLinkStore
+	fileStorageUsage     store.FileStorageUsageStore
+	blockedDomain        store.BlockedDomainStore
+	webSocketOutbox      store.WebSocketOutboxStore
+	postIdempotencyKey   store.PostIdempotencyKeyStore
 	role                 store.RoleStore
 }
 
@@ -100,49 +119,51 @@ type SqlSupplier struct {
 	srCounter      int64
 	next           store.LayeredStoreSupplier
 	master         *gorp.DbMap
+	masterLock     sync.RWMutex
 	replicas       []*gorp.DbMap
 	searchReplicas []*gorp.DbMap
 	oldStores      SqlSupplierOldStores
 	settings       *model.SqlSettings
+	metrics        einterfaces.MetricsInterface
+	cacheProvider  *utils.CacheProvider
+
+	// indexPlan is set while CheckSchema collects the indexes that the stores expect, which stops them from being
+	// created or removed.
+	indexPlan *schemaIndexPlan
+
+	// fullUnicode is false if the database can't store emoji and other characters that take four bytes in UTF-8.
+	fullUnicode bool
+
+	// masterUnhealthy is set by the health check while the master database can't be reached.
+	masterUnhealthy int32
+	healthCheckStop chan struct{}
+	healthCheckDone chan struct{}
+
+	// masterDataSource is the data source of the master database, which changes if it fails over to a standby.
+	masterDataSource string
+
+	// readOnlyMaster is used in place of the master database while the store is read-only, either because readOnly
+	// has been set or because the health check has fallen back to the replica at fallbackDataSource. It's connected
+	// to readOnlyDataSource. These are guarded by masterLock.
+	readOnlyMaster     *gorp.DbMap
+	readOnlyDataSource string
+	readOnly           bool
+	fallbackDataSource string
 }
 
-func NewSqlSupplier(settings model.SqlSettings, metrics einterfaces.MetricsInterface) *SqlSupplier {
-	supplier := &SqlSupplier{
-		rrCounter: 0,
-		srCounter: 0,
-		settings:  &settings,
-	}
+// NewSqlSupplier creates a supplier for the database in settings, creating and upgrading its schema first while
+// holding the migration lock. Its caches are created by cacheProvider, or with their default sizes if it's nil.
+func NewSqlSupplier(settings model.SqlSettings, metrics einterfaces.MetricsInterface, cacheProvider *utils.CacheProvider) *SqlSupplier {
+	supplier := newSqlSupplier(settings, metrics, cacheProvider)
 
-	supplier.initConnection()
+	unlock, err := supplier.LockMigrations()
+	if err != nil {
+		mlog.Critical(fmt.Sprintf("Error locking the database for migrations: %v", err))
+		time.Sleep(time.Second)
+		os.Exit(EXIT_MIGRATION_LOCK)
+	}
 
-	supplier.oldStores.team = NewSqlTeamStore(supplier)
-	supplier.oldStores.channel = NewSqlChannelStore(supplier, metrics)
-	supplier.oldStores.post = NewSqlPostStore(supplier, metrics)
-	supplier.oldStores.user = NewSqlUserStore(supplier, metrics)
-	supplier.oldStores.audit = NewSqlAuditStore(supplier)
-	supplier.oldStores.cluster = NewSqlClusterDiscoveryStore(supplier)
-	supplier.oldStores.compliance = NewSqlComplianceStore(supplier)
-	supplier.oldStores.session = NewSqlSessionStore(supplier)
-	supplier.oldStores.oauth = NewSqlOAuthStore(supplier)
-	supplier.oldStores.system = NewSqlSystemStore(supplier)
-	supplier.oldStores.webhook = NewSqlWebhookStore(supplier, metrics)
-	supplier.oldStores.command = NewSqlCommandStore(supplier)
-	supplier.oldStores.commandWebhook = NewSqlCommandWebhookStore(supplier)
-	supplier.oldStores.preference = NewSqlPreferenceStore(supplier)
-	supplier.oldStores.license = NewSqlLicenseStore(supplier)
-	supplier.oldStores.token = NewSqlTokenStore(supplier)
-	supplier.oldStores.emoji = NewSqlEmojiStore(supplier, metrics)
-	supplier.oldStores.status = NewSqlStatusStore(supplier)
-	supplier.oldStores.fileInfo = NewSqlFileInfoStore(supplier, metrics)
-	supplier.oldStores.job = NewSqlJobStore(supplier)
-	supplier.oldStores.userAccessToken = NewSqlUserAccessTokenStore(supplier)
-	supplier.oldStores.channelMemberHistory = NewSqlChannelMemberHistoryStore(supplier)
-	supplier.oldStores.plugin = NewSqlPluginStore(supplier)
-
-	initSqlSupplierReactions(supplier)
-	initSqlSupplierRoles(supplier)
-
-	err := supplier.GetMaster().CreateTablesIfNotExists()
+	err = supplier.GetMaster().CreateTablesIfNotExists()
 	if err != nil {
 		mlog.Critical(fmt.Sprintf("Error creating database tables: %v", err))
 		time.Sleep(time.Second)
@@ -151,33 +172,157 @@ func NewSqlSupplier(settings model.SqlSettings, metrics einterfaces.MetricsInter
 
 	UpgradeDatabase(supplier)
 
-	supplier.oldStores.team.(*SqlTeamStore).CreateIndexesIfNotExists()
-	supplier.oldStores.channel.(*SqlChannelStore).CreateIndexesIfNotExists()
-	supplier.oldStores.post.(*SqlPostStore).CreateIndexesIfNotExists()
-	supplier.oldStores.user.(*SqlUserStore).CreateIndexesIfNotExists()
-	supplier.oldStores.audit.(*SqlAuditStore).CreateIndexesIfNotExists()
-	supplier.oldStores.compliance.(*SqlComplianceStore).CreateIndexesIfNotExists()
-	supplier.oldStores.session.(*SqlSessionStore).CreateIndexesIfNotExists()
-	supplier.oldStores.oauth.(*SqlOAuthStore).CreateIndexesIfNotExists()
-	supplier.oldStores.system.(*SqlSystemStore).CreateIndexesIfNotExists()
-	supplier.oldStores.webhook.(*SqlWebhookStore).CreateIndexesIfNotExists()
-	supplier.oldStores.command.(*SqlCommandStore).CreateIndexesIfNotExists()
-	supplier.oldStores.commandWebhook.(*SqlCommandWebhookStore).CreateIndexesIfNotExists()
-	supplier.oldStores.preference.(*SqlPreferenceStore).CreateIndexesIfNotExists()
-	supplier.oldStores.license.(*SqlLicenseStore).CreateIndexesIfNotExists()
-	supplier.oldStores.token.(*SqlTokenStore).CreateIndexesIfNotExists()
-	supplier.oldStores.emoji.(*SqlEmojiStore).CreateIndexesIfNotExists()
-	supplier.oldStores.status.(*SqlStatusStore).CreateIndexesIfNotExists()
-	supplier.oldStores.fileInfo.(*SqlFileInfoStore).CreateIndexesIfNotExists()
-	supplier.oldStores.job.(*SqlJobStore).CreateIndexesIfNotExists()
-	supplier.oldStores.userAccessToken.(*SqlUserAccessTokenStore).CreateIndexesIfNotExists()
-	supplier.oldStores.plugin.(*SqlPluginStore).CreateIndexesIfNotExists()
+	supplier.createIndexesIfNotExists()
 
 	supplier.oldStores.preference.(*SqlPreferenceStore).DeleteUnusedFeatures()
 
+	unlock()
+
+	supplier.start()
+
 	return supplier
 }
 
+// NewSqlSupplierWithoutMigrations creates a supplier for the database in settings without creating or upgrading its
+// schema, for servers whose database is migrated separately. CheckSchemaIsCurrent should be used first to make sure
+// that it has been.
+func NewSqlSupplierWithoutMigrations(settings model.SqlSettings, metrics einterfaces.MetricsInterface, cacheProvider *utils.CacheProvider) *SqlSupplier {
+	supplier := newSqlSupplier(settings, metrics, cacheProvider)
+	supplier.start()
+
+	return supplier
+}
+
+func newSqlSupplier(settings model.SqlSettings, metrics einterfaces.MetricsInterface, cacheProvider *utils.CacheProvider) *SqlSupplier {
+	if cacheProvider == nil {
+		cacheProvider = utils.NewCacheProvider(model.CacheSettings{}, metrics, nil)
+	}
+
+	supplier := &SqlSupplier{
+		rrCounter:     0,
+		srCounter:     0,
+		settings:      &settings,
+		metrics:       metrics,
+		cacheProvider: cacheProvider,
+	}
+
+	supplier.initConnection()
+	supplier.initStores()
+
+	return supplier
+}
+
+// start checks what the database supports and starts checking its health.
+func (ss *SqlSupplier) start() {
+	ss.fullUnicode = ss.checkFullUnicode()
+
+	ss.startHealthCheck()
+}
+
+// NewSqlSupplierForSchemaCheck connects to the database in settings without creating, upgrading or otherwise changing
+// anything in it, so that its schema can be checked with CheckSchema or converted with MigrateCharset.
+func NewSqlSupplierForSchemaCheck(settings model.SqlSettings) *SqlSupplier {
+	supplier := &SqlSupplier{
+		settings:      &settings,
+		cacheProvider: utils.NewCacheProvider(model.CacheSettings{}, nil, nil),
+	}
+
+	supplier.initConnection()
+	supplier.initStores()
+
+	return supplier
+}
+
+// initStores creates the stores, which map their tables without touching the database.
+func (ss *SqlSupplier) initStores() {
+	metrics := ss.metrics
+
+	ss.oldStores.team = NewSqlTeamStore(ss)
+	ss.oldStores.channel = NewSqlChannelStore(ss, metrics)
+	ss.oldStores.post = NewSqlPostStore(ss, metrics)
+	ss.oldStores.user = NewSqlUserStore(ss, metrics)
+	ss.oldStores.audit = NewSqlAuditStore(ss)
+	ss.oldStores.cluster = NewSqlClusterDiscoveryStore(ss)
+	ss.oldStores.compliance = NewSqlComplianceStore(ss)
+	ss.oldStores.session = NewSqlSessionStore(ss)
+	ss.oldStores.oauth = NewSqlOAuthStore(ss)
+	ss.oldStores.system = NewSqlSystemStore(ss)
+	ss.oldStores.webhook = NewSqlWebhookStore(ss, metrics)
+	ss.oldStores.command = NewSqlCommandStore(ss)
+	ss.oldStores.commandWebhook = NewSqlCommandWebhookStore(ss)
+	ss.oldStores.preference = NewSqlPreferenceStore(ss)
+	ss.oldStores.license = NewSqlLicenseStore(ss)
+	ss.oldStores.token = NewSqlTokenStore(ss)
+	ss.oldStores.emoji = NewSqlEmojiStore(ss, metrics)
+	ss.oldStores.status = NewSqlStatusStore(ss)
+	ss.oldStores.fileInfo = NewSqlFileInfoStore(ss, metrics)
+	ss.oldStores.job = NewSqlJobStore(ss)
+	ss.oldStores.userAccessToken = NewSqlUserAccessTokenStore(ss)
+	ss.oldStores.channelMemberHistory = NewSqlChannelMemberHistoryStore(ss)
+	ss.oldStores.thread = NewSqlThreadStore(ss)
+	ss.oldStores.customGroup = NewSqlCustomGroupStore(ss)
+	ss.oldStores.userAttribute = NewSqlUserAttributeStore(ss)
+	ss.oldStores.retentionPolicy = NewSqlRetentionPolicyStore(ss)
+	ss.oldStores.termsOfService = NewSqlTermsOfServiceStore(ss)
+	ss.oldStores.analyticsDaily = NewSqlAnalyticsDailyStore(ss)
+	ss.oldStores.provisioningToken = NewSqlProvisioningTokenStore(ss)
+	ss.oldStores.teamTemplate = NewSqlTeamTemplateStore(ss)
+	ss.oldStores.inviteLink = NewSqlInviteLinkStore(ss)
+	ss.oldStores.teamInvitation = NewSqlTeamInvitationStore(ss)
+	ss.oldStores.shortPermalink = NewSqlShortPermalinkStore(ss)
+	ss.oldStores.configurationHistory = NewSqlConfigurationHistoryStore(ss)
+	ss.oldStores.filePublicLink = NewSqlFilePublicLinkStore(ss)
+	ss.oldStores.fileStorageUsage = NewSqlFileStorageUsageStore(ss)
+	ss.oldStores.blockedDomain = NewSqlBlockedDomainStore(ss)
+	ss.oldStores.webSocketOutbox = NewSqlWebSocketOutboxStore(ss)
+	ss.oldStores.postIdempotencyKey = NewSqlPostIdempotencyKeyStore(ss)
+	ss.oldStores.plugin = NewSqlPluginStore(ss)
+
+	initSqlSupplierReactions(ss)
+	initSqlSupplierRoles(ss)
+}
+
+// createIndexesIfNotExists creates every index that the stores use.
+func (ss *SqlSupplier) createIndexesIfNotExists() {
+	ss.oldStores.team.(*SqlTeamStore).CreateIndexesIfNotExists()
+	ss.oldStores.channel.(*SqlChannelStore).CreateIndexesIfNotExists()
+	ss.oldStores.post.(*SqlPostStore).CreateIndexesIfNotExists()
+	ss.oldStores.user.(*SqlUserStore).CreateIndexesIfNotExists()
+	ss.oldStores.audit.(*SqlAuditStore).CreateIndexesIfNotExists()
+	ss.oldStores.compliance.(*SqlComplianceStore).CreateIndexesIfNotExists()
+	ss.oldStores.session.(*SqlSessionStore).CreateIndexesIfNotExists()
+	ss.oldStores.oauth.(*SqlOAuthStore).CreateIndexesIfNotExists()
+	ss.oldStores.system.(*SqlSystemStore).CreateIndexesIfNotExists()
+	ss.oldStores.webhook.(*SqlWebhookStore).CreateIndexesIfNotExists()
+	ss.oldStores.command.(*SqlCommandStore).CreateIndexesIfNotExists()
+	ss.oldStores.commandWebhook.(*SqlCommandWebhookStore).CreateIndexesIfNotExists()
+	ss.oldStores.preference.(*SqlPreferenceStore).CreateIndexesIfNotExists()
+	ss.oldStores.license.(*SqlLicenseStore).CreateIndexesIfNotExists()
+	ss.oldStores.token.(*SqlTokenStore).CreateIndexesIfNotExists()
+	ss.oldStores.emoji.(*SqlEmojiStore).CreateIndexesIfNotExists()
+	ss.oldStores.status.(*SqlStatusStore).CreateIndexesIfNotExists()
+	ss.oldStores.fileInfo.(*SqlFileInfoStore).CreateIndexesIfNotExists()
+	ss.oldStores.job.(*SqlJobStore).CreateIndexesIfNotExists()
+	ss.oldStores.userAccessToken.(*SqlUserAccessTokenStore).CreateIndexesIfNotExists()
+	ss.oldStores.plugin.(*SqlPluginStore).CreateIndexesIfNotExists()
+	ss.oldStores.thread.(*SqlThreadStore).CreateIndexesIfNotExists()
+	ss.oldStores.customGroup.(*SqlCustomGroupStore).CreateIndexesIfNotExists()
+	ss.oldStores.userAttribute.(*SqlUserAttributeStore).CreateIndexesIfNotExists()
+	ss.oldStores.retentionPolicy.(*SqlRetentionPolicyStore).CreateIndexesIfNotExists()
+	ss.oldStores.termsOfService.(*SqlTermsOfServiceStore).CreateIndexesIfNotExists()
+	ss.oldStores.analyticsDaily.(*SqlAnalyticsDailyStore).CreateIndexesIfNotExists()
+	ss.oldStores.provisioningToken.(*SqlProvisioningTokenStore).CreateIndexesIfNotExists()
+	ss.oldStores.teamTemplate.(*SqlTeamTemplateStore).CreateIndexesIfNotExists()
+	ss.oldStores.inviteLink.(*SqlInviteLinkStore).CreateIndexesIfNotExists()
+	ss.oldStores.teamInvitation.(*SqlTeamInvitationStore).CreateIndexesIfNotExists()
+	ss.oldStores.shortPermalink.(*SqlShortPermalinkStore).CreateIndexesIfNotExists()
+	ss.oldStores.configurationHistory.(*SqlConfigurationHistoryStore).CreateIndexesIfNotExists()
+	ss.oldStores.filePublicLink.(*SqlFilePublicLinkStore).CreateIndexesIfNotExists()
+	ss.oldStores.fileStorageUsage.(*SqlFileStorageUsageStore).CreateIndexesIfNotExists()
+	ss.oldStores.webSocketOutbox.(*SqlWebSocketOutboxStore).CreateIndexesIfNotExists()
+	ss.oldStores.postIdempotencyKey.(*SqlPostIdempotencyKeyStore).CreateIndexesIfNotExists()
+}
+
 func (s *SqlSupplier) SetChainNext(next store.LayeredStoreSupplier) {
 	s.next = next
 }
@@ -213,9 +358,7 @@ func setupConnection(con_type string, dataSource string, settings *model.SqlSett
 		}
 	}
 
-	db.SetMaxIdleConns(*settings.MaxIdleConns)
-	db.SetMaxOpenConns(*settings.MaxOpenConns)
-	db.SetConnMaxLifetime(time.Duration(MAX_DB_CONN_LIFETIME) * time.Minute)
+	configureConnection(db, settings)
 
 	var dbmap *gorp.DbMap
 
@@ -240,8 +383,15 @@ func setupConnection(con_type string, dataSource string, settings *model.SqlSett
 	return dbmap
 }
 
+func configureConnection(db *dbsql.DB, settings *model.SqlSettings) {
+	db.SetMaxIdleConns(*settings.MaxIdleConns)
+	db.SetMaxOpenConns(*settings.MaxOpenConns)
+	db.SetConnMaxLifetime(time.Duration(MAX_DB_CONN_LIFETIME) * time.Minute)
+}
+
 func (s *SqlSupplier) initConnection() {
 	s.master = setupConnection("master", *s.settings.DataSource, s.settings)
+	s.masterDataSource = *s.settings.DataSource
 
 	if len(s.settings.DataSourceReplicas) > 0 {
 		s.replicas = make([]*gorp.DbMap, len(s.settings.DataSourceReplicas))
@@ -268,9 +418,18 @@ func (ss *SqlSupplier) GetCurrentSchemaVersion() string {
 }
 
 func (ss *SqlSupplier) GetMaster() *gorp.DbMap {
+	ss.masterLock.RLock()
+	defer ss.masterLock.RUnlock()
+	if ss.readOnlyMaster != nil {
+		return ss.readOnlyMaster
+	}
 	return ss.master
 }
 
+func (ss *SqlSupplier) GetCacheProvider() *utils.CacheProvider {
+	return ss.cacheProvider
+}
+
 func (ss *SqlSupplier) GetSearchReplica() *gorp.DbMap {
 	if len(ss.settings.DataSourceSearchReplicas) == 0 {
 		return ss.GetReplica()
@@ -605,10 +764,11 @@ func (ss *SqlSupplier) CreateFullTextIndexIfNotExists(indexName string, tableNam
 }
 
 func (ss *SqlSupplier) createIndexIfNotExists(indexName string, tableName string, columnNames []string, indexType string, unique bool) bool {
+	index := &schemaIndex{name: indexName, table: tableName, columns: columnNames, indexType: indexType, unique: unique}
 
-	uniqueStr := ""
-	if unique {
-		uniqueStr = "UNIQUE "
+	if ss.indexPlan != nil {
+		ss.indexPlan.created = append(ss.indexPlan.created, index)
+		return false
 	}
 
 	if ss.DriverName() == model.DATABASE_DRIVER_POSTGRES {
@@ -618,20 +778,12 @@ func (ss *SqlSupplier) createIndexIfNotExists(indexName string, tableName string
 			return false
 		}
 
-		query := ""
-		if indexType == INDEX_TYPE_FULL_TEXT {
-			if len(columnNames) != 1 {
-				mlog.Critical("Unable to create multi column full text index")
-				os.Exit(EXIT_CREATE_INDEX_POSTGRES)
-			}
-			columnName := columnNames[0]
-			postgresColumnNames := convertMySQLFullTextColumnsToPostgres(columnName)
-			query = "CREATE INDEX " + indexName + " ON " + tableName + " USING gin(to_tsvector('english', " + postgresColumnNames + "))"
-		} else {
-			query = "CREATE " + uniqueStr + "INDEX " + indexName + " ON " + tableName + " (" + strings.Join(columnNames, ", ") + ")"
+		if indexType == INDEX_TYPE_FULL_TEXT && len(columnNames) != 1 {
+			mlog.Critical("Unable to create multi column full text index")
+			os.Exit(EXIT_CREATE_INDEX_POSTGRES)
 		}
 
-		_, err := ss.GetMaster().ExecNoTimeout(query)
+		_, err := ss.GetMaster().ExecNoTimeout(ss.createIndexQuery(index, false))
 		if err != nil {
 			mlog.Critical(fmt.Sprintf("Failed to create index %v, %v", errExists, err))
 			time.Sleep(time.Second)
@@ -650,19 +802,14 @@ func (ss *SqlSupplier) createIndexIfNotExists(indexName string, tableName string
 			return false
 		}
 
-		fullTextIndex := ""
-		if indexType == INDEX_TYPE_FULL_TEXT {
-			fullTextIndex = " FULLTEXT "
-		}
-
-		_, err = ss.GetMaster().ExecNoTimeout("CREATE  " + uniqueStr + fullTextIndex + " INDEX " + indexName + " ON " + tableName + " (" + strings.Join(columnNames, ", ") + ")")
+		_, err = ss.GetMaster().ExecNoTimeout(ss.createIndexQuery(index, false))
 		if err != nil {
 			mlog.Critical(fmt.Sprintf("Failed to create index %v", err))
 			time.Sleep(time.Second)
 			os.Exit(EXIT_CREATE_INDEX_FULL_MYSQL)
 		}
 	} else if ss.DriverName() == model.DATABASE_DRIVER_SQLITE {
-		_, err := ss.GetMaster().ExecNoTimeout("CREATE INDEX IF NOT EXISTS " + indexName + " ON " + tableName + " (" + strings.Join(columnNames, ", ") + ")")
+		_, err := ss.GetMaster().ExecNoTimeout(ss.createIndexQuery(index, false))
 		if err != nil {
 			mlog.Critical(fmt.Sprintf("Failed to create index %v", err))
 			time.Sleep(time.Second)
@@ -677,7 +824,50 @@ func (ss *SqlSupplier) createIndexIfNotExists(indexName string, tableName string
 	return true
 }
 
+// createIndexQuery returns the statement that creates an index. If online is true, the index is built without
+// blocking writes to its table where the database supports it, which is slower than building it normally.
+func (ss *SqlSupplier) createIndexQuery(index *schemaIndex, online bool) string {
+	uniqueStr := ""
+	if index.unique {
+		uniqueStr = "UNIQUE "
+	}
+
+	if ss.DriverName() == model.DATABASE_DRIVER_POSTGRES {
+		concurrently := ""
+		if online {
+			concurrently = "CONCURRENTLY "
+		}
+
+		if index.indexType == INDEX_TYPE_FULL_TEXT {
+			postgresColumnNames := convertMySQLFullTextColumnsToPostgres(index.columns[0])
+			return "CREATE INDEX " + concurrently + index.name + " ON " + index.table + " USING gin(to_tsvector('english', " + postgresColumnNames + "))"
+		}
+
+		return "CREATE " + uniqueStr + "INDEX " + concurrently + index.name + " ON " + index.table + " (" + strings.Join(index.columns, ", ") + ")"
+	} else if ss.DriverName() == model.DATABASE_DRIVER_MYSQL {
+		fullTextIndex := ""
+		if index.indexType == INDEX_TYPE_FULL_TEXT {
+			fullTextIndex = "FULLTEXT "
+		}
+
+		query := "CREATE " + uniqueStr + fullTextIndex + "INDEX " + index.name + " ON " + index.table + " (" + strings.Join(index.columns, ", ") + ")"
+
+		// Full text indexes can't be built without locking their tables
+		if online && index.indexType != INDEX_TYPE_FULL_TEXT {
+			query += " ALGORITHM=INPLACE LOCK=NONE"
+		}
+
+		return query
+	}
+
+	return "CREATE INDEX IF NOT EXISTS " + index.name + " ON " + index.table + " (" + strings.Join(index.columns, ", ") + ")"
+}
+
 func (ss *SqlSupplier) RemoveIndexIfExists(indexName string, tableName string) bool {
+	if ss.indexPlan != nil {
+		ss.indexPlan.removed = append(ss.indexPlan.removed, &schemaIndex{name: indexName, table: tableName})
+		return false
+	}
 
 	if ss.DriverName() == model.DATABASE_DRIVER_POSTGRES {
 		_, err := ss.GetMaster().SelectStr("SELECT $1::regclass", indexName)
@@ -753,13 +943,16 @@ func IsUniqueConstraintError(err error, indexName []string) bool {
 func (ss *SqlSupplier) GetAllConns() []*gorp.DbMap {
 	all := make([]*gorp.DbMap, len(ss.replicas)+1)
 	copy(all, ss.replicas)
-	all[len(ss.replicas)] = ss.master
+	all[len(ss.replicas)] = ss.writableMaster()
 	return all
 }
 
 func (ss *SqlSupplier) Close() {
 	mlog.Info("Closing SqlStore")
-	ss.master.Db.Close()
+	ss.stopHealthCheck()
+	ss.SetReadOnly(false)
+	ss.stopFallingBackToReplica()
+	ss.writableMaster().Db.Close()
 	for _, replica := range ss.replicas {
 		replica.Db.Close()
 	}
@@ -857,6 +1050,74 @@ func (ss *SqlSupplier) ChannelMemberHistory() store.ChannelMemberHistoryStore {
 	return ss.oldStores.channelMemberHistory
 }
 
+func (ss *SqlSupplier) Thread() store.ThreadStore {
+	return ss.oldStores.thread
+}
+
+func (ss *SqlSupplier) CustomGroup() store.CustomGroupStore {
+	return ss.oldStores.customGroup
+}
+
+func (ss *SqlSupplier) UserAttribute() store.UserAttributeStore {
+	return ss.oldStores.userAttribute
+}
+
+func (ss *SqlSupplier) RetentionPolicy() store.RetentionPolicyStore {
+	return ss.oldStores.retentionPolicy
+}
+
+func (ss *SqlSupplier) TermsOfService() store.TermsOfServiceStore {
+	return ss.oldStores.termsOfService
+}
+
+func (ss *SqlSupplier) AnalyticsDaily() store.AnalyticsDailyStore {
+	return ss.oldStores.analyticsDaily
+}
+
+func (ss *SqlSupplier) ProvisioningToken() store.ProvisioningTokenStore {
+	return ss.oldStores.provisioningToken
+}
+
+func (ss *SqlSupplier) TeamTemplate() store.TeamTemplateStore {
+	return ss.oldStores.teamTemplate
+}
+
+func (ss *SqlSupplier) InviteLink() store.InviteLinkStore {
+	return ss.oldStores.inviteLink
+}
+
+func (ss *SqlSupplier) TeamInvitation() store.TeamInvitationStore {
+	return ss.oldStores.teamInvitation
+}
+
+func (ss *SqlSupplier) ShortPermalink() store.ShortPermalinkStore {
+	return ss.oldStores.shortPermalink
+}
+
+func (ss *SqlSupplier) ConfigurationHistory() store.ConfigurationHistoryStore {
+	return ss.oldStores.configurationHistory
+}
+
+func (ss *SqlSupplier) FilePublicLink() store.FilePublicLinkStore {
+	return ss.oldStores.filePublicLink
+}
+
+func (ss *SqlSupplier) FileStorageUsage() store.FileStorageUsageStore {
+	return ss.oldStores.fileStorageUsage
+}
+
+func (ss *SqlSupplier) BlockedDomain() store.BlockedDomainStore {
+	return ss.oldStores.blockedDomain
+}
+
+func (ss *SqlSupplier) WebSocketOutbox() store.WebSocketOutboxStore {
+	return ss.oldStores.webSocketOutbox
+}
+
+func (ss *SqlSupplier) PostIdempotencyKey() store.PostIdempotencyKeyStore {
+	return ss.oldStores.postIdempotencyKey
+}
+
 func (ss *SqlSupplier) Plugin() store.PluginStore {
 	return ss.oldStores.plugin
 }
@@ -866,7 +1127,7 @@ func (ss *SqlSupplier) Role() store.RoleStore {
 }
 
 func (ss *SqlSupplier) DropAllTables() {
-	ss.master.TruncateTables()
+	ss.GetMaster().TruncateTables()
 }
 
 type mattermConverter struct{}
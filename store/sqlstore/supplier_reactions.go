@@ -4,9 +4,11 @@
 package sqlstore
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/mattermost/gorp"
 	"github.com/mattermost/mattermost-server/mlog"
@@ -34,7 +36,7 @@ func (s *SqlSupplier) ReactionSave(ctx context.Context, reaction *model.Reaction
 	if transaction, err := s.GetMaster().Begin(); err != nil {
 		result.Err = model.NewAppError("SqlReactionStore.Save", "store.sql_reaction.save.begin.app_error", nil, err.Error(), http.StatusInternalServerError)
 	} else {
-		err := saveReactionAndUpdatePost(transaction, reaction)
+		err := saveReactionAndUpdatePost(transaction, reaction, store.WebSocketOutboxEventFromContext(ctx))
 
 		if err != nil {
 			transaction.Rollback()
@@ -64,7 +66,7 @@ func (s *SqlSupplier) ReactionDelete(ctx context.Context, reaction *model.Reacti
 	if transaction, err := s.GetMaster().Begin(); err != nil {
 		result.Err = model.NewAppError("SqlReactionStore.Delete", "store.sql_reaction.delete.begin.app_error", nil, err.Error(), http.StatusInternalServerError)
 	} else {
-		err := deleteReactionAndUpdatePost(transaction, reaction)
+		err := deleteReactionAndUpdatePost(transaction, reaction, store.WebSocketOutboxEventFromContext(ctx))
 
 		if err != nil {
 			transaction.Rollback()
@@ -103,6 +105,88 @@ func (s *SqlSupplier) ReactionGetForPost(ctx context.Context, postId string, hin
 	return result
 }
 
+func (s *SqlSupplier) ReactionGetForUser(ctx context.Context, userId string, hints ...store.LayeredStoreHint) *store.LayeredStoreSupplierResult {
+	result := store.NewSupplierResult()
+
+	var reactions []*model.Reaction
+
+	if _, err := s.GetReplica().Select(&reactions,
+		`SELECT
+				*
+			FROM
+				Reactions
+			WHERE
+				UserId = :UserId
+			ORDER BY
+				CreateAt`, map[string]interface{}{"UserId": userId}); err != nil {
+		result.Err = model.NewAppError("SqlReactionStore.GetForUser", "store.sql_reaction.get_for_user.app_error", nil, "", http.StatusInternalServerError)
+	} else {
+		result.Data = reactions
+	}
+
+	return result
+}
+
+func (s *SqlSupplier) ReactionGetCountsForPosts(ctx context.Context, postIds []string, userId string, hints ...store.LayeredStoreHint) *store.LayeredStoreSupplierResult {
+	result := store.NewSupplierResult()
+
+	if len(postIds) == 0 {
+		result.Data = []*model.ReactionCount{}
+		return result
+	}
+
+	keys := bytes.Buffer{}
+	params := map[string]interface{}{"UserId": userId}
+	for i, postId := range postIds {
+		if keys.Len() > 0 {
+			keys.WriteString(",")
+		}
+
+		key := "PostId" + strconv.Itoa(i)
+		keys.WriteString(":" + key)
+		params[key] = postId
+	}
+
+	var rows []struct {
+		PostId    string
+		EmojiName string
+		Count     int64
+		Me        int
+	}
+
+	if _, err := s.GetReplica().Select(&rows,
+		`SELECT
+				PostId,
+				EmojiName,
+				COUNT(*) AS Count,
+				MAX(CASE WHEN UserId = :UserId THEN 1 ELSE 0 END) AS Me
+			FROM
+				Reactions
+			WHERE
+				PostId IN (`+keys.String()+`)
+			GROUP BY
+				PostId, EmojiName
+			ORDER BY
+				MIN(CreateAt)`, params); err != nil {
+		result.Err = model.NewAppError("SqlReactionStore.GetCountsForPosts", "store.sql_reaction.get_counts_for_posts.app_error", nil, err.Error(), http.StatusInternalServerError)
+		return result
+	}
+
+	counts := make([]*model.ReactionCount, 0, len(rows))
+	for _, row := range rows {
+		counts = append(counts, &model.ReactionCount{
+			PostId:    row.PostId,
+			EmojiName: row.EmojiName,
+			Count:     row.Count,
+			Me:        row.Me == 1,
+		})
+	}
+
+	result.Data = counts
+
+	return result
+}
+
 func (s *SqlSupplier) ReactionDeleteAllWithEmojiName(ctx context.Context, emojiName string, hints ...store.LayeredStoreHint) *store.LayeredStoreSupplierResult {
 	result := store.NewSupplierResult()
 
@@ -168,15 +252,23 @@ func (s *SqlSupplier) ReactionPermanentDeleteBatch(ctx context.Context, endTime
 	return result
 }
 
-func saveReactionAndUpdatePost(transaction *gorp.Transaction, reaction *model.Reaction) error {
+// saveReactionAndUpdatePost also saves the websocket event for the reaction if one is given.
+func saveReactionAndUpdatePost(transaction *gorp.Transaction, reaction *model.Reaction, event *model.WebSocketOutboxEvent) error {
 	if err := transaction.Insert(reaction); err != nil {
 		return err
 	}
 
+	if event != nil {
+		if err := saveWebSocketOutboxEvent(transaction, event); err != nil {
+			return err
+		}
+	}
+
 	return updatePostForReactionsOnInsert(transaction, reaction.PostId)
 }
 
-func deleteReactionAndUpdatePost(transaction *gorp.Transaction, reaction *model.Reaction) error {
+// deleteReactionAndUpdatePost also saves the websocket event for the deletion if one is given.
+func deleteReactionAndUpdatePost(transaction *gorp.Transaction, reaction *model.Reaction, event *model.WebSocketOutboxEvent) error {
 	if _, err := transaction.Exec(
 		`DELETE FROM
 			Reactions
@@ -188,6 +280,12 @@ func deleteReactionAndUpdatePost(transaction *gorp.Transaction, reaction *model.
 		return err
 	}
 
+	if event != nil {
+		if err := saveWebSocketOutboxEvent(transaction, event); err != nil {
+			return err
+		}
+	}
+
 	return updatePostForReactionsOnDelete(transaction, reaction.PostId)
 }
 
@@ -85,7 +85,7 @@ func TestGetReplica(t *testing.T) {
 				DataSourceReplicas:       testCase.DataSourceReplicas,
 				DataSourceSearchReplicas: testCase.DataSourceSearchReplicas,
 			}
-			supplier := sqlstore.NewSqlSupplier(settings, nil)
+			supplier := sqlstore.NewSqlSupplier(settings, nil, nil)
 
 			replicas := make(map[*gorp.DbMap]bool)
 			for i := 0; i < 5; i++ {
@@ -221,7 +221,7 @@ func TestGetAllConns(t *testing.T) {
 				DataSourceReplicas:       testCase.DataSourceReplicas,
 				DataSourceSearchReplicas: testCase.DataSourceSearchReplicas,
 			}
-			supplier := sqlstore.NewSqlSupplier(settings, nil)
+			supplier := sqlstore.NewSqlSupplier(settings, nil, nil)
 
 			assert.Equal(t, testCase.ExpectedNumConnections, len(supplier.GetAllConns()))
 		})
@@ -0,0 +1,124 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+type SqlTeamInvitationStore struct {
+	SqlStore
+}
+
+func NewSqlTeamInvitationStore(sqlStore SqlStore) store.TeamInvitationStore {
+	s := &SqlTeamInvitationStore{sqlStore}
+
+	for _, db := range sqlStore.GetAllConns() {
+		table := db.AddTableWithName(model.TeamInvitation{}, "TeamInvitations").SetKeys(false, "Id")
+		table.ColMap("Id").SetMaxSize(26)
+		table.ColMap("TeamId").SetMaxSize(26)
+		table.ColMap("SenderId").SetMaxSize(26)
+		table.ColMap("Email").SetMaxSize(model.USER_EMAIL_MAX_LENGTH)
+		table.ColMap("Role").SetMaxSize(16)
+		table.ColMap("Channels").SetMaxSize(model.TEAM_INVITATION_CHANNELS_MAX_LENGTH)
+		table.ColMap("Message").SetMaxSize(model.INVITE_MESSAGE_MAX_RUNES * 4)
+		table.ColMap("Status").SetMaxSize(16)
+		table.ColMap("Reason").SetMaxSize(model.TEAM_INVITATION_REASON_MAX_RUNES * 4)
+	}
+
+	return s
+}
+
+func (s SqlTeamInvitationStore) CreateIndexesIfNotExists() {
+	s.CreateIndexIfNotExists("idx_team_invitations_team_id", "TeamInvitations", "TeamId")
+	s.CreateIndexIfNotExists("idx_team_invitations_email", "TeamInvitations", "Email")
+}
+
+func (s SqlTeamInvitationStore) Save(invitation *model.TeamInvitation) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		invitation.PreSave()
+
+		if result.Err = invitation.IsValid(); result.Err != nil {
+			return
+		}
+
+		if err := s.GetMaster().Insert(invitation); err != nil {
+			result.Err = model.NewAppError("SqlTeamInvitationStore.Save", "store.sql_team_invitation.save.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = invitation
+		}
+	})
+}
+
+func (s SqlTeamInvitationStore) Get(invitationId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		invitation := model.TeamInvitation{}
+
+		if err := s.GetMaster().SelectOne(&invitation, "SELECT * FROM TeamInvitations WHERE Id = :Id", map[string]interface{}{"Id": invitationId}); err != nil {
+			if err == sql.ErrNoRows {
+				result.Err = model.NewAppError("SqlTeamInvitationStore.Get", "store.sql_team_invitation.get.app_error", nil, "id="+invitationId+", "+err.Error(), http.StatusNotFound)
+			} else {
+				result.Err = model.NewAppError("SqlTeamInvitationStore.Get", "store.sql_team_invitation.get.app_error", nil, "id="+invitationId+", "+err.Error(), http.StatusInternalServerError)
+			}
+		} else {
+			result.Data = &invitation
+		}
+	})
+}
+
+// GetForTeam returns a page of the invitations that have been sent for the team, with the newest first.
+func (s SqlTeamInvitationStore) GetForTeam(teamId string, offset int, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var invitations []*model.TeamInvitation
+
+		if _, err := s.GetReplica().Select(&invitations, "SELECT * FROM TeamInvitations WHERE TeamId = :TeamId ORDER BY CreateAt DESC, Id LIMIT :Limit OFFSET :Offset", map[string]interface{}{"TeamId": teamId, "Limit": limit, "Offset": offset}); err != nil {
+			result.Err = model.NewAppError("SqlTeamInvitationStore.GetForTeam", "store.sql_team_invitation.get_for_team.app_error", nil, "team_id="+teamId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = invitations
+		}
+	})
+}
+
+// UpdateStatus records whether the invitation has been queued again, sent or failed, and why if it failed.
+func (s SqlTeamInvitationStore) UpdateStatus(invitationId string, status string, reason string, updateAt int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if _, err := s.GetMaster().Exec(
+			"UPDATE TeamInvitations SET Status = :Status, Reason = :Reason, UpdateAt = :UpdateAt WHERE Id = :Id",
+			map[string]interface{}{"Id": invitationId, "Status": status, "Reason": model.TruncateTeamInvitationReason(reason), "UpdateAt": updateAt}); err != nil {
+			result.Err = model.NewAppError("SqlTeamInvitationStore.UpdateStatus", "store.sql_team_invitation.update_status.app_error", nil, "id="+invitationId+", "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// MarkBounced marks the invitations that have been sent to the email address as failed after they've bounced,
+// returning how many were marked.
+func (s SqlTeamInvitationStore) MarkBounced(email string, reason string, updateAt int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		sqlResult, err := s.GetMaster().Exec(
+			"UPDATE TeamInvitations SET Status = :Failed, Reason = :Reason, UpdateAt = :UpdateAt WHERE Email = :Email AND Status = :Sent",
+			map[string]interface{}{
+				"Email":    email,
+				"Failed":   model.TEAM_INVITATION_STATUS_FAILED,
+				"Sent":     model.TEAM_INVITATION_STATUS_SENT,
+				"Reason":   model.TruncateTeamInvitationReason(reason),
+				"UpdateAt": updateAt,
+			})
+		if err != nil {
+			result.Err = model.NewAppError("SqlTeamInvitationStore.MarkBounced", "store.sql_team_invitation.mark_bounced.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rowsAffected, err := sqlResult.RowsAffected()
+		if err != nil {
+			result.Err = model.NewAppError("SqlTeamInvitationStore.MarkBounced", "store.sql_team_invitation.mark_bounced.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = rowsAffected
+	})
+}
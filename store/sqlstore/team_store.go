@@ -8,8 +8,11 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/mattermost/gorp"
+
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/store"
+	"github.com/mattermost/mattermost-server/utils"
 )
 
 const (
@@ -18,10 +21,15 @@ const (
 
 type SqlTeamStore struct {
 	SqlStore
+
+	filteredWordsCache *utils.Cache
 }
 
 func NewSqlTeamStore(sqlStore SqlStore) store.TeamStore {
-	s := &SqlTeamStore{sqlStore}
+	s := &SqlTeamStore{
+		SqlStore:           sqlStore,
+		filteredWordsCache: sqlStore.GetCacheProvider().GetCache(model.CACHE_TEAM_FILTERED_WORDS),
+	}
 
 	for _, db := range sqlStore.GetAllConns() {
 		table := db.AddTableWithName(model.Team{}, "Teams").SetKeys(false, "Id")
@@ -33,11 +41,20 @@ func NewSqlTeamStore(sqlStore SqlStore) store.TeamStore {
 		table.ColMap("CompanyName").SetMaxSize(64)
 		table.ColMap("AllowedDomains").SetMaxSize(500)
 		table.ColMap("InviteId").SetMaxSize(32)
+		table.ColMap("WelcomeMessage").SetMaxSize(model.TEAM_WELCOME_MESSAGE_MAX_RUNES)
 
 		tablem := db.AddTableWithName(model.TeamMember{}, "TeamMembers").SetKeys(false, "TeamId", "UserId")
 		tablem.ColMap("TeamId").SetMaxSize(26)
 		tablem.ColMap("UserId").SetMaxSize(26)
 		tablem.ColMap("Roles").SetMaxSize(64)
+
+		tabled := db.AddTableWithName(teamDefaultChannel{}, "TeamDefaultChannels").SetKeys(false, "TeamId", "ChannelId")
+		tabled.ColMap("TeamId").SetMaxSize(26)
+		tabled.ColMap("ChannelId").SetMaxSize(26)
+
+		tablef := db.AddTableWithName(teamFilteredWord{}, "TeamFilteredWords").SetKeys(false, "TeamId", "Word")
+		tablef.ColMap("TeamId").SetMaxSize(26)
+		tablef.ColMap("Word").SetMaxSize(model.FILTERED_WORD_MAX_RUNES * 4)
 	}
 
 	return s
@@ -100,7 +117,7 @@ func (s SqlTeamStore) Update(team *model.Team) store.StoreChannel {
 			team.UpdateAt = model.GetMillis()
 			team.Name = oldTeam.Name
 
-			if count, err := s.GetMaster().Update(team); err != nil {
+			if count, err := s.GetMaster().UpdateColumns(excludeTeamMemberCount, team); err != nil {
 				result.Err = model.NewAppError("SqlTeamStore.Update", "store.sql_team.update.updating.app_error", nil, "id="+team.Id+", "+err.Error(), http.StatusInternalServerError)
 			} else if count != 1 {
 				result.Err = model.NewAppError("SqlTeamStore.Update", "store.sql_team.update.app_error", nil, "id="+team.Id, http.StatusInternalServerError)
@@ -111,6 +128,12 @@ func (s SqlTeamStore) Update(team *model.Team) store.StoreChannel {
 	})
 }
 
+// excludeTeamMemberCount stops an update to a team from overwriting its member count, since the team being updated
+// may have been read before members last joined or left it.
+func excludeTeamMemberCount(col *gorp.ColumnMap) bool {
+	return col.ColumnName != "MemberCount"
+}
+
 func (s SqlTeamStore) UpdateDisplayName(name string, teamId string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		if _, err := s.GetMaster().Exec("UPDATE Teams SET DisplayName = :Name WHERE Id = :Id", map[string]interface{}{"Name": name, "Id": teamId}); err != nil {
@@ -311,7 +334,38 @@ func (s SqlTeamStore) PermanentDelete(teamId string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		if _, err := s.GetMaster().Exec("DELETE FROM Teams WHERE Id = :TeamId", map[string]interface{}{"TeamId": teamId}); err != nil {
 			result.Err = model.NewAppError("SqlTeamStore.Delete", "store.sql_team.permanent_delete.app_error", nil, "teamId="+teamId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := s.GetMaster().Exec("DELETE FROM TeamDefaultChannels WHERE TeamId = :TeamId", map[string]interface{}{"TeamId": teamId}); err != nil {
+			result.Err = model.NewAppError("SqlTeamStore.Delete", "store.sql_team.permanent_delete.app_error", nil, "teamId="+teamId+", "+err.Error(), http.StatusInternalServerError)
+			return
 		}
+
+		if _, err := s.GetMaster().Exec("DELETE FROM TeamFilteredWords WHERE TeamId = :TeamId", map[string]interface{}{"TeamId": teamId}); err != nil {
+			result.Err = model.NewAppError("SqlTeamStore.Delete", "store.sql_team.permanent_delete.app_error", nil, "teamId="+teamId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		s.GetCacheProvider().Invalidate(model.CACHE_TEAM_FILTERED_WORDS, teamId)
+	})
+}
+
+// GetDataCounts returns how many rows of each of the tables that are deleted along with a team, by the name of the
+// table, belong to the team.
+func (s SqlTeamStore) GetDataCounts(teamId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		counts := map[string]int64{}
+		for _, table := range []string{"Channels", "TeamMembers", "Commands", "IncomingWebhooks", "OutgoingWebhooks"} {
+			count, err := s.GetMaster().SelectInt("SELECT COUNT(*) FROM "+table+" WHERE TeamId = :TeamId", map[string]interface{}{"TeamId": teamId})
+			if err != nil {
+				result.Err = model.NewAppError("SqlTeamStore.GetDataCounts", "store.sql_team.get_data_counts.app_error", nil, "team_id="+teamId+", table="+table+", "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			counts[table] = count
+		}
+
+		result.Data = counts
 	})
 }
 
@@ -353,16 +407,73 @@ func (s SqlTeamStore) SaveMember(member *model.TeamMember, maxUsersPerTeam int)
 			}
 		}
 
-		if err := s.GetMaster().Insert(member); err != nil {
+		transaction, err := s.GetMaster().Begin()
+		if err != nil {
+			result.Err = model.NewAppError("SqlTeamStore.SaveMember", "store.sql_team.save_member.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := transaction.Insert(member); err != nil {
+			transaction.Rollback()
 			if IsUniqueConstraintError(err, []string{"TeamId", "teammembers_pkey", "PRIMARY"}) {
 				result.Err = model.NewAppError("SqlTeamStore.SaveMember", TEAM_MEMBER_EXISTS_ERROR, nil, "team_id="+member.TeamId+", user_id="+member.UserId+", "+err.Error(), http.StatusBadRequest)
 			} else {
 				result.Err = model.NewAppError("SqlTeamStore.SaveMember", "store.sql_team.save_member.save.app_error", nil, "team_id="+member.TeamId+", user_id="+member.UserId+", "+err.Error(), http.StatusInternalServerError)
 			}
-		} else {
-			result.Data = member
+			return
+		}
+
+		if err := updateTeamMemberCountsT(transaction, member.UserId, member.TeamId, 1); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlTeamStore.SaveMember", "store.sql_team.update_member_counts.app_error", nil, "team_id="+member.TeamId+", user_id="+member.UserId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlTeamStore.SaveMember", "store.sql_team.save_member.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
 		}
+
+		result.Data = member
+	})
+}
+
+// updateTeamMemberCountsT adds delta to the member count of a team if the given user is an active member of it, or
+// to those of every team that they're an active member of if teamId is empty. Changes to a membership are counted
+// by calling this with a delta of -1 before making them and 1 afterwards.
+func updateTeamMemberCountsT(transaction *gorp.Transaction, userId string, teamId string, delta int) error {
+	query := `
+		UPDATE
+			Teams
+		SET
+			MemberCount = MemberCount + :Delta * (
+				SELECT
+					COUNT(*)
+				FROM
+					TeamMembers,
+					Users
+				WHERE
+					TeamMembers.TeamId = Teams.Id
+					AND TeamMembers.UserId = :UserId
+					AND TeamMembers.DeleteAt = 0
+					AND Users.Id = TeamMembers.UserId
+					AND Users.DeleteAt = 0
+			)
+		WHERE
+			`
+
+	if teamId == "" {
+		query += "Id IN (SELECT TeamId FROM TeamMembers WHERE UserId = :UserId)"
+	} else {
+		query += "Id = :TeamId"
+	}
+
+	_, err := transaction.Exec(query, map[string]interface{}{
+		"Delta":  delta,
+		"UserId": userId,
+		"TeamId": teamId,
 	})
+	return err
 }
 
 func (s SqlTeamStore) UpdateMember(member *model.TeamMember) store.StoreChannel {
@@ -373,11 +484,38 @@ func (s SqlTeamStore) UpdateMember(member *model.TeamMember) store.StoreChannel
 			return
 		}
 
-		if _, err := s.GetMaster().Update(member); err != nil {
+		transaction, err := s.GetMaster().Begin()
+		if err != nil {
+			result.Err = model.NewAppError("SqlTeamStore.UpdateMember", "store.sql_team.save_member.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// The member is taken out of the team's count as they were and added back as they are now, since they may be
+		// leaving or rejoining it
+		if err := updateTeamMemberCountsT(transaction, member.UserId, member.TeamId, -1); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlTeamStore.UpdateMember", "store.sql_team.update_member_counts.app_error", nil, "team_id="+member.TeamId+", user_id="+member.UserId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := transaction.Update(member); err != nil {
+			transaction.Rollback()
 			result.Err = model.NewAppError("SqlTeamStore.UpdateMember", "store.sql_team.save_member.save.app_error", nil, err.Error(), http.StatusInternalServerError)
-		} else {
-			result.Data = member
+			return
+		}
+
+		if err := updateTeamMemberCountsT(transaction, member.UserId, member.TeamId, 1); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlTeamStore.UpdateMember", "store.sql_team.update_member_counts.app_error", nil, "team_id="+member.TeamId+", user_id="+member.UserId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlTeamStore.UpdateMember", "store.sql_team.save_member.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
 		}
+
+		result.Data = member
 	})
 }
 
@@ -431,21 +569,102 @@ func (s SqlTeamStore) GetTotalMemberCount(teamId string) store.StoreChannel {
 
 func (s SqlTeamStore) GetActiveMemberCount(teamId string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
-		count, err := s.GetReplica().SelectInt(`
+		count, err := s.GetReplica().SelectInt("SELECT MemberCount FROM Teams WHERE Id = :TeamId", map[string]interface{}{"TeamId": teamId})
+		if err != nil {
+			result.Err = model.NewAppError("SqlTeamStore.GetActiveMemberCount", "store.sql_team.get_member_count.app_error", nil, "teamId="+teamId+" "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = count
+		}
+	})
+}
+
+// GetAllMemberCounts returns the number of active members of every team that hasn't been deleted, ordered by name.
+func (s SqlTeamStore) GetAllMemberCounts() store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		counts := []*model.TeamSeatCount{}
+		if _, err := s.GetReplica().Select(&counts, `
 			SELECT
-				count(*)
+				Id AS TeamId,
+				Name,
+				DisplayName,
+				MemberCount AS ActiveMemberCount
+			FROM
+				Teams
+			WHERE
+				DeleteAt = 0
+			ORDER BY
+				Name`); err != nil {
+			result.Err = model.NewAppError("SqlTeamStore.GetAllMemberCounts", "store.sql_team.get_all_member_counts.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = counts
+		}
+	})
+}
+
+// ReconcileMemberCounts recounts the active members of up to limit teams, ordered by id, after the one with the given
+// id, and fixes the counts of any where they've drifted.
+func (s SqlTeamStore) ReconcileMemberCounts(afterTeamId string, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var teamIds []string
+		if _, err := s.GetMaster().Select(&teamIds, "SELECT Id FROM Teams WHERE Id > :AfterTeamId ORDER BY Id LIMIT :Limit", map[string]interface{}{"AfterTeamId": afterTeamId, "Limit": limit}); err != nil {
+			result.Err = model.NewAppError("SqlTeamStore.ReconcileMemberCounts", "store.sql_team.reconcile_member_counts.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if len(teamIds) == 0 {
+			result.Data = &model.TeamMemberCountsBatch{}
+			return
+		}
+
+		props := make(map[string]interface{})
+		idQuery := ""
+
+		for index, teamId := range teamIds {
+			if len(idQuery) > 0 {
+				idQuery += ", "
+			}
+
+			props["teamId"+strconv.Itoa(index)] = teamId
+			idQuery += ":teamId" + strconv.Itoa(index)
+		}
+
+		memberCount := `(
+			SELECT
+				COUNT(*)
 			FROM
 				TeamMembers,
 				Users
 			WHERE
-				TeamMembers.UserId = Users.Id
-				AND TeamMembers.TeamId = :TeamId
+				TeamMembers.TeamId = Teams.Id
 				AND TeamMembers.DeleteAt = 0
-				AND Users.DeleteAt = 0`, map[string]interface{}{"TeamId": teamId})
+				AND TeamMembers.UserId = Users.Id
+				AND Users.DeleteAt = 0
+		)`
+
+		query := `
+			UPDATE
+				Teams
+			SET
+				MemberCount = ` + memberCount + `
+			WHERE
+				Id IN (` + idQuery + `)
+				AND MemberCount != ` + memberCount
+
+		sqlResult, err := s.GetMaster().Exec(query, props)
 		if err != nil {
-			result.Err = model.NewAppError("SqlTeamStore.GetActiveMemberCount", "store.sql_team.get_member_count.app_error", nil, "teamId="+teamId+" "+err.Error(), http.StatusInternalServerError)
-		} else {
-			result.Data = count
+			result.Err = model.NewAppError("SqlTeamStore.ReconcileMemberCounts", "store.sql_team.reconcile_member_counts.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		corrected, err := sqlResult.RowsAffected()
+		if err != nil {
+			result.Err = model.NewAppError("SqlTeamStore.ReconcileMemberCounts", "store.sql_team.reconcile_member_counts.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = &model.TeamMemberCountsBatch{
+			LastTeamId: teamIds[len(teamIds)-1],
+			Corrected:  corrected,
 		}
 	})
 }
@@ -535,31 +754,67 @@ func (s SqlTeamStore) GetChannelUnreadsForTeam(teamId, userId string) store.Stor
 
 func (s SqlTeamStore) RemoveMember(teamId string, userId string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
-		_, err := s.GetMaster().Exec("DELETE FROM TeamMembers WHERE TeamId = :TeamId AND UserId = :UserId", map[string]interface{}{"TeamId": teamId, "UserId": userId})
-		if err != nil {
-			result.Err = model.NewAppError("SqlChannelStore.RemoveMember", "store.sql_team.remove_member.app_error", nil, "team_id="+teamId+", user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
-		}
+		result.Err = s.removeMembers(userId, teamId, "DELETE FROM TeamMembers WHERE TeamId = :TeamId AND UserId = :UserId", "team_id="+teamId+", user_id="+userId)
 	})
 }
 
 func (s SqlTeamStore) RemoveAllMembersByTeam(teamId string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
-		_, err := s.GetMaster().Exec("DELETE FROM TeamMembers WHERE TeamId = :TeamId", map[string]interface{}{"TeamId": teamId})
+		transaction, err := s.GetMaster().Begin()
 		if err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.RemoveMember", "store.sql_team.remove_member.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := transaction.Exec("DELETE FROM TeamMembers WHERE TeamId = :TeamId", map[string]interface{}{"TeamId": teamId}); err != nil {
+			transaction.Rollback()
 			result.Err = model.NewAppError("SqlChannelStore.RemoveMember", "store.sql_team.remove_member.app_error", nil, "team_id="+teamId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := transaction.Exec("UPDATE Teams SET MemberCount = 0 WHERE Id = :TeamId", map[string]interface{}{"TeamId": teamId}); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlChannelStore.RemoveMember", "store.sql_team.update_member_counts.app_error", nil, "team_id="+teamId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlChannelStore.RemoveMember", "store.sql_team.remove_member.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
 		}
 	})
 }
 
 func (s SqlTeamStore) RemoveAllMembersByUser(userId string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
-		_, err := s.GetMaster().Exec("DELETE FROM TeamMembers WHERE UserId = :UserId", map[string]interface{}{"UserId": userId})
-		if err != nil {
-			result.Err = model.NewAppError("SqlChannelStore.RemoveMember", "store.sql_team.remove_member.app_error", nil, "user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
-		}
+		result.Err = s.removeMembers(userId, "", "DELETE FROM TeamMembers WHERE UserId = :UserId", "user_id="+userId)
 	})
 }
 
+// removeMembers deletes a user's memberships with the given query, taking them out of the member counts of the
+// teams that they're leaving, which is only the one with teamId unless it's empty.
+func (s SqlTeamStore) removeMembers(userId string, teamId string, query string, details string) *model.AppError {
+	transaction, err := s.GetMaster().Begin()
+	if err != nil {
+		return model.NewAppError("SqlChannelStore.RemoveMember", "store.sql_team.remove_member.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	if err := updateTeamMemberCountsT(transaction, userId, teamId, -1); err != nil {
+		transaction.Rollback()
+		return model.NewAppError("SqlChannelStore.RemoveMember", "store.sql_team.update_member_counts.app_error", nil, details+", "+err.Error(), http.StatusInternalServerError)
+	}
+
+	if _, err := transaction.Exec(query, map[string]interface{}{"TeamId": teamId, "UserId": userId}); err != nil {
+		transaction.Rollback()
+		return model.NewAppError("SqlChannelStore.RemoveMember", "store.sql_team.remove_member.app_error", nil, details+", "+err.Error(), http.StatusInternalServerError)
+	}
+
+	if err := transaction.Commit(); err != nil {
+		return model.NewAppError("SqlChannelStore.RemoveMember", "store.sql_team.remove_member.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
 func (us SqlTeamStore) UpdateLastTeamIconUpdate(teamId string, curTime int64) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		if _, err := us.GetMaster().Exec("UPDATE Teams SET LastTeamIconUpdate = :Time, UpdateAt = :Time WHERE Id = :teamId", map[string]interface{}{"Time": curTime, "teamId": teamId}); err != nil {
@@ -569,3 +824,114 @@ func (us SqlTeamStore) UpdateLastTeamIconUpdate(teamId string, curTime int64) st
 		}
 	})
 }
+
+// teamDefaultChannel is a row recording that new members of a team should be added to a channel
+// in addition to Town Square and Off-Topic.
+type teamDefaultChannel struct {
+	TeamId    string
+	ChannelId string
+}
+
+func (s SqlTeamStore) GetDefaultChannels(teamId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var channelIds []string
+		if _, err := s.GetReplica().Select(&channelIds, "SELECT ChannelId FROM TeamDefaultChannels WHERE TeamId = :TeamId ORDER BY ChannelId", map[string]interface{}{"TeamId": teamId}); err != nil {
+			result.Err = model.NewAppError("SqlTeamStore.GetDefaultChannels", "store.sql_team.default_channels.get.app_error", nil, "team_id="+teamId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = channelIds
+	})
+}
+
+func (s SqlTeamStore) UpdateDefaultChannels(teamId string, channelIds []string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		transaction, err := s.GetMaster().Begin()
+		if err != nil {
+			result.Err = model.NewAppError("SqlTeamStore.UpdateDefaultChannels", "store.sql_team.default_channels.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := transaction.Exec("DELETE FROM TeamDefaultChannels WHERE TeamId = :TeamId", map[string]interface{}{"TeamId": teamId}); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlTeamStore.UpdateDefaultChannels", "store.sql_team.default_channels.update.app_error", nil, "team_id="+teamId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		for _, channelId := range channelIds {
+			if err := transaction.Insert(&teamDefaultChannel{TeamId: teamId, ChannelId: channelId}); err != nil {
+				transaction.Rollback()
+				result.Err = model.NewAppError("SqlTeamStore.UpdateDefaultChannels", "store.sql_team.default_channels.update.app_error", nil, "team_id="+teamId+", channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlTeamStore.UpdateDefaultChannels", "store.sql_team.default_channels.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = channelIds
+	})
+}
+
+// teamFilteredWord is a row recording a word that's filtered from posts on a team
+type teamFilteredWord struct {
+	TeamId string
+	Word   string
+}
+
+// GetFilteredWords returns a team's filtered words in alphabetical order. They're cached until the list is
+// updated on any server in the cluster.
+func (s SqlTeamStore) GetFilteredWords(teamId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if cacheItem, ok := s.filteredWordsCache.Get(teamId); ok {
+			result.Data = cacheItem.([]string)
+			return
+		}
+
+		words := []string{}
+		if _, err := s.GetReplica().Select(&words, "SELECT Word FROM TeamFilteredWords WHERE TeamId = :TeamId ORDER BY Word", map[string]interface{}{"TeamId": teamId}); err != nil {
+			result.Err = model.NewAppError("SqlTeamStore.GetFilteredWords", "store.sql_team.filtered_words.get.app_error", nil, "team_id="+teamId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		s.filteredWordsCache.AddWithDefaultExpires(teamId, words)
+
+		result.Data = words
+	})
+}
+
+// UpdateFilteredWords replaces a team's filtered words.
+func (s SqlTeamStore) UpdateFilteredWords(teamId string, words []string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		transaction, err := s.GetMaster().Begin()
+		if err != nil {
+			result.Err = model.NewAppError("SqlTeamStore.UpdateFilteredWords", "store.sql_team.filtered_words.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := transaction.Exec("DELETE FROM TeamFilteredWords WHERE TeamId = :TeamId", map[string]interface{}{"TeamId": teamId}); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlTeamStore.UpdateFilteredWords", "store.sql_team.filtered_words.update.app_error", nil, "team_id="+teamId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		for _, word := range words {
+			if err := transaction.Insert(&teamFilteredWord{TeamId: teamId, Word: word}); err != nil {
+				transaction.Rollback()
+				result.Err = model.NewAppError("SqlTeamStore.UpdateFilteredWords", "store.sql_team.filtered_words.update.app_error", nil, "team_id="+teamId+", "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlTeamStore.UpdateFilteredWords", "store.sql_team.filtered_words.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		s.GetCacheProvider().Invalidate(model.CACHE_TEAM_FILTERED_WORDS, teamId)
+
+		result.Data = words
+	})
+}
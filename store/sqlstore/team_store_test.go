@@ -6,9 +6,76 @@ package sqlstore
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
 	"github.com/mattermost/mattermost-server/store/storetest"
 )
 
 func TestTeamStore(t *testing.T) {
 	StoreTest(t, storetest.TestTeamStore)
 }
+
+func TestTeamStoreReconcileMemberCounts(t *testing.T) {
+	StoreTest(t, func(t *testing.T, ss store.Store) {
+		sqlStore := ss.(*store.LayeredStore).DatabaseLayer.(SqlStore)
+
+		reconcileAll := func() int64 {
+			var corrected int64
+			afterTeamId := ""
+			for {
+				result := <-ss.Team().ReconcileMemberCounts(afterTeamId, 10)
+				require.Nil(t, result.Err)
+
+				batch := result.Data.(*model.TeamMemberCountsBatch)
+				corrected += batch.Corrected
+				if batch.LastTeamId == "" {
+					return corrected
+				}
+
+				require.True(t, batch.LastTeamId > afterTeamId, "should have moved on to the next batch")
+				afterTeamId = batch.LastTeamId
+			}
+		}
+
+		saveTeam := func(displayName string) *model.Team {
+			return store.Must(ss.Team().Save(&model.Team{
+				DisplayName: displayName,
+				Name:        "zz" + model.NewId() + "b",
+				Email:       model.NewId() + "@nowhere.com",
+				Type:        model.TEAM_OPEN,
+			})).(*model.Team)
+		}
+
+		drifted := saveTeam("Drifted")
+		correct := saveTeam("Correct")
+
+		user := store.Must(ss.User().Save(&model.User{Email: model.NewId()})).(*model.User)
+		user2 := store.Must(ss.User().Save(&model.User{Email: model.NewId()})).(*model.User)
+		inactive := store.Must(ss.User().Save(&model.User{Email: model.NewId(), DeleteAt: model.GetMillis()})).(*model.User)
+
+		for _, teamId := range []string{drifted.Id, correct.Id} {
+			for _, userId := range []string{user.Id, user2.Id, inactive.Id} {
+				store.Must(ss.Team().SaveMember(&model.TeamMember{TeamId: teamId, UserId: userId}, -1))
+			}
+		}
+
+		// Start from a clean slate in case earlier tests left their teams' counts out of date
+		reconcileAll()
+
+		_, err := sqlStore.GetMaster().Exec("UPDATE Teams SET MemberCount = 42 WHERE Id = :TeamId", map[string]interface{}{"TeamId": drifted.Id})
+		require.Nil(t, err)
+
+		assert.Equal(t, int64(1), reconcileAll())
+
+		for _, teamId := range []string{drifted.Id, correct.Id} {
+			result := <-ss.Team().GetActiveMemberCount(teamId)
+			require.Nil(t, result.Err)
+			assert.Equal(t, int64(2), result.Data.(int64))
+		}
+
+		assert.Equal(t, int64(0), reconcileAll(), "shouldn't have anything left to fix")
+	})
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+type SqlTeamTemplateStore struct {
+	SqlStore
+}
+
+func NewSqlTeamTemplateStore(sqlStore SqlStore) store.TeamTemplateStore {
+	s := &SqlTeamTemplateStore{sqlStore}
+
+	for _, db := range sqlStore.GetAllConns() {
+		table := db.AddTableWithName(model.TeamTemplate{}, "TeamTemplates").SetKeys(false, "Id")
+		table.ColMap("Id").SetMaxSize(26)
+		table.ColMap("Name").SetMaxSize(model.TEAM_TEMPLATE_NAME_MAX_LENGTH).SetUnique(true)
+		table.ColMap("Description").SetMaxSize(model.TEAM_TEMPLATE_DESCRIPTION_MAX_LENGTH)
+		table.ColMap("CreatorId").SetMaxSize(26)
+		table.ColMap("Content").SetMaxSize(model.TEAM_TEMPLATE_CONTENT_MAX_LENGTH)
+	}
+
+	return s
+}
+
+func (s SqlTeamTemplateStore) CreateIndexesIfNotExists() {
+}
+
+func (s SqlTeamTemplateStore) Save(template *model.TeamTemplate) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		template.PreSave()
+
+		if result.Err = template.IsValid(); result.Err != nil {
+			return
+		}
+
+		if err := s.GetMaster().Insert(template); err != nil {
+			if IsUniqueConstraintError(err, []string{"Name", "teamtemplates_name_key"}) {
+				result.Err = model.NewAppError("SqlTeamTemplateStore.Save", "store.sql_team_template.save.name_exists.app_error", map[string]interface{}{"Name": template.Name}, err.Error(), http.StatusBadRequest)
+			} else {
+				result.Err = model.NewAppError("SqlTeamTemplateStore.Save", "store.sql_team_template.save.app_error", nil, err.Error(), http.StatusInternalServerError)
+			}
+		} else {
+			result.Data = template
+		}
+	})
+}
+
+func (s SqlTeamTemplateStore) Update(template *model.TeamTemplate) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		template.PreUpdate()
+
+		if result.Err = template.IsValid(); result.Err != nil {
+			return
+		}
+
+		if count, err := s.GetMaster().Update(template); err != nil {
+			if IsUniqueConstraintError(err, []string{"Name", "teamtemplates_name_key"}) {
+				result.Err = model.NewAppError("SqlTeamTemplateStore.Update", "store.sql_team_template.save.name_exists.app_error", map[string]interface{}{"Name": template.Name}, err.Error(), http.StatusBadRequest)
+			} else {
+				result.Err = model.NewAppError("SqlTeamTemplateStore.Update", "store.sql_team_template.update.app_error", nil, "id="+template.Id+", "+err.Error(), http.StatusInternalServerError)
+			}
+		} else if count != 1 {
+			result.Err = model.NewAppError("SqlTeamTemplateStore.Update", "store.sql_team_template.get.app_error", nil, "id="+template.Id, http.StatusNotFound)
+		} else {
+			result.Data = template
+		}
+	})
+}
+
+func (s SqlTeamTemplateStore) Get(templateId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		template := model.TeamTemplate{}
+
+		if err := s.GetReplica().SelectOne(&template, "SELECT * FROM TeamTemplates WHERE Id = :Id", map[string]interface{}{"Id": templateId}); err != nil {
+			if err == sql.ErrNoRows {
+				result.Err = model.NewAppError("SqlTeamTemplateStore.Get", "store.sql_team_template.get.app_error", nil, "id="+templateId+", "+err.Error(), http.StatusNotFound)
+			} else {
+				result.Err = model.NewAppError("SqlTeamTemplateStore.Get", "store.sql_team_template.get.app_error", nil, "id="+templateId+", "+err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		template.LoadContent()
+		result.Data = &template
+	})
+}
+
+func (s SqlTeamTemplateStore) GetByName(name string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		template := model.TeamTemplate{}
+
+		if err := s.GetReplica().SelectOne(&template, "SELECT * FROM TeamTemplates WHERE Name = :Name", map[string]interface{}{"Name": name}); err != nil {
+			if err == sql.ErrNoRows {
+				result.Err = model.NewAppError("SqlTeamTemplateStore.GetByName", "store.sql_team_template.get_by_name.app_error", nil, "name="+name+", "+err.Error(), http.StatusNotFound)
+			} else {
+				result.Err = model.NewAppError("SqlTeamTemplateStore.GetByName", "store.sql_team_template.get_by_name.app_error", nil, "name="+name+", "+err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		template.LoadContent()
+		result.Data = &template
+	})
+}
+
+func (s SqlTeamTemplateStore) GetAll(offset int, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		templates := []*model.TeamTemplate{}
+
+		if _, err := s.GetReplica().Select(&templates, "SELECT * FROM TeamTemplates ORDER BY Name LIMIT :Limit OFFSET :Offset", map[string]interface{}{"Offset": offset, "Limit": limit}); err != nil {
+			result.Err = model.NewAppError("SqlTeamTemplateStore.GetAll", "store.sql_team_template.get_all.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		for _, template := range templates {
+			template.LoadContent()
+		}
+
+		result.Data = templates
+	})
+}
+
+func (s SqlTeamTemplateStore) Delete(templateId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if _, err := s.GetMaster().Exec("DELETE FROM TeamTemplates WHERE Id = :Id", map[string]interface{}{"Id": templateId}); err != nil {
+			result.Err = model.NewAppError("SqlTeamTemplateStore.Delete", "store.sql_team_template.delete.app_error", nil, "id="+templateId+", "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+type SqlTermsOfServiceStore struct {
+	SqlStore
+}
+
+func NewSqlTermsOfServiceStore(sqlStore SqlStore) store.TermsOfServiceStore {
+	s := &SqlTermsOfServiceStore{
+		SqlStore: sqlStore,
+	}
+
+	for _, db := range sqlStore.GetAllConns() {
+		table := db.AddTableWithName(model.TermsOfService{}, "TermsOfService").SetKeys(false, "Id")
+		table.ColMap("Id").SetMaxSize(26)
+		table.ColMap("UserId").SetMaxSize(26)
+		table.ColMap("Text").SetMaxSize(model.TERMS_OF_SERVICE_TEXT_MAX_RUNES)
+
+		tableUsers := db.AddTableWithName(model.UserTermsOfService{}, "UserTermsOfService").SetKeys(false, "UserId")
+		tableUsers.ColMap("UserId").SetMaxSize(26)
+		tableUsers.ColMap("TermsOfServiceId").SetMaxSize(26)
+	}
+
+	return s
+}
+
+func (s SqlTermsOfServiceStore) CreateIndexesIfNotExists() {
+	s.CreateIndexIfNotExists("idx_termsofservice_create_at", "TermsOfService", "CreateAt")
+}
+
+func (s SqlTermsOfServiceStore) Save(termsOfService *model.TermsOfService) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if len(termsOfService.Id) > 0 {
+			result.Err = model.NewAppError("SqlTermsOfServiceStore.Save", "store.sql_terms_of_service.save.existing.app_error", nil, "id="+termsOfService.Id, http.StatusBadRequest)
+			return
+		}
+
+		termsOfService.PreSave()
+		if result.Err = termsOfService.IsValid(); result.Err != nil {
+			return
+		}
+
+		if err := s.GetMaster().Insert(termsOfService); err != nil {
+			result.Err = model.NewAppError("SqlTermsOfServiceStore.Save", "store.sql_terms_of_service.save.app_error", nil, "id="+termsOfService.Id+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = termsOfService
+		}
+	})
+}
+
+func (s SqlTermsOfServiceStore) Get(id string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var termsOfService model.TermsOfService
+		if err := s.GetReplica().SelectOne(&termsOfService, "SELECT * FROM TermsOfService WHERE Id = :Id", map[string]interface{}{"Id": id}); err != nil {
+			if err == sql.ErrNoRows {
+				result.Err = model.NewAppError("SqlTermsOfServiceStore.Get", "store.sql_terms_of_service.get.missing.app_error", nil, "id="+id, http.StatusNotFound)
+			} else {
+				result.Err = model.NewAppError("SqlTermsOfServiceStore.Get", "store.sql_terms_of_service.get.app_error", nil, "id="+id+", "+err.Error(), http.StatusInternalServerError)
+			}
+		} else {
+			result.Data = &termsOfService
+		}
+	})
+}
+
+func (s SqlTermsOfServiceStore) GetLatest() store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var termsOfService model.TermsOfService
+		if err := s.GetReplica().SelectOne(&termsOfService, "SELECT * FROM TermsOfService ORDER BY CreateAt DESC LIMIT 1"); err != nil {
+			if err == sql.ErrNoRows {
+				result.Err = model.NewAppError("SqlTermsOfServiceStore.GetLatest", "store.sql_terms_of_service.get.no_rows.app_error", nil, "", http.StatusNotFound)
+			} else {
+				result.Err = model.NewAppError("SqlTermsOfServiceStore.GetLatest", "store.sql_terms_of_service.get.app_error", nil, err.Error(), http.StatusInternalServerError)
+			}
+		} else {
+			result.Data = &termsOfService
+		}
+	})
+}
+
+func (s SqlTermsOfServiceStore) SaveUserTermsOfService(userTermsOfService *model.UserTermsOfService) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		userTermsOfService.PreSave()
+		if result.Err = userTermsOfService.IsValid(); result.Err != nil {
+			return
+		}
+
+		// Users only ever have the latest version they accepted recorded
+		if count, err := s.GetMaster().Update(userTermsOfService); err != nil {
+			result.Err = model.NewAppError("SqlTermsOfServiceStore.SaveUserTermsOfService", "store.sql_terms_of_service.save_user.app_error", nil, "user_id="+userTermsOfService.UserId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		} else if count == 0 {
+			if err := s.GetMaster().Insert(userTermsOfService); err != nil {
+				result.Err = model.NewAppError("SqlTermsOfServiceStore.SaveUserTermsOfService", "store.sql_terms_of_service.save_user.app_error", nil, "user_id="+userTermsOfService.UserId+", "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		result.Data = userTermsOfService
+	})
+}
+
+func (s SqlTermsOfServiceStore) GetUserTermsOfService(userId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var userTermsOfService model.UserTermsOfService
+		if err := s.GetReplica().SelectOne(&userTermsOfService, "SELECT * FROM UserTermsOfService WHERE UserId = :UserId", map[string]interface{}{"UserId": userId}); err != nil {
+			if err == sql.ErrNoRows {
+				result.Err = model.NewAppError("SqlTermsOfServiceStore.GetUserTermsOfService", "store.sql_terms_of_service.get_user.missing.app_error", nil, "user_id="+userId, http.StatusNotFound)
+			} else {
+				result.Err = model.NewAppError("SqlTermsOfServiceStore.GetUserTermsOfService", "store.sql_terms_of_service.get_user.app_error", nil, "user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+			}
+		} else {
+			result.Data = &userTermsOfService
+		}
+	})
+}
@@ -0,0 +1,338 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+type SqlThreadStore struct {
+	SqlStore
+}
+
+func NewSqlThreadStore(sqlStore SqlStore) store.ThreadStore {
+	s := &SqlThreadStore{
+		SqlStore: sqlStore,
+	}
+
+	for _, db := range sqlStore.GetAllConns() {
+		tableThreads := db.AddTableWithName(model.Thread{}, "Threads").SetKeys(false, "PostId")
+		tableThreads.ColMap("PostId").SetMaxSize(26)
+		tableThreads.ColMap("ChannelId").SetMaxSize(26)
+		tableThreads.ColMap("Participants").SetMaxSize(8000)
+
+		tableThreadMemberships := db.AddTableWithName(model.ThreadMembership{}, "ThreadMemberships").SetKeys(false, "PostId", "UserId")
+		tableThreadMemberships.ColMap("PostId").SetMaxSize(26)
+		tableThreadMemberships.ColMap("UserId").SetMaxSize(26)
+	}
+
+	return s
+}
+
+func (s SqlThreadStore) CreateIndexesIfNotExists() {
+	s.CreateIndexIfNotExists("idx_threads_channel_id", "Threads", "ChannelId")
+	s.CreateIndexIfNotExists("idx_threads_last_reply_at", "Threads", "LastReplyAt")
+	s.CreateIndexIfNotExists("idx_threadmemberships_user_id", "ThreadMemberships", "UserId")
+}
+
+func (s SqlThreadStore) Save(thread *model.Thread) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if result.Err = thread.IsValid(); result.Err != nil {
+			return
+		}
+
+		if err := s.GetMaster().Insert(thread); err != nil {
+			if IsUniqueConstraintError(err, []string{"PostId", "threads_pkey", "PRIMARY"}) {
+				result.Err = model.NewAppError("SqlThreadStore.Save", "store.sql_thread.save.exists.app_error", nil, "post_id="+thread.PostId+", "+err.Error(), http.StatusBadRequest)
+			} else {
+				result.Err = model.NewAppError("SqlThreadStore.Save", "store.sql_thread.save.app_error", nil, "post_id="+thread.PostId+", "+err.Error(), http.StatusInternalServerError)
+			}
+		} else {
+			result.Data = thread
+		}
+	})
+}
+
+func (s SqlThreadStore) Update(thread *model.Thread) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if result.Err = thread.IsValid(); result.Err != nil {
+			return
+		}
+
+		if _, err := s.GetMaster().Update(thread); err != nil {
+			result.Err = model.NewAppError("SqlThreadStore.Update", "store.sql_thread.update.app_error", nil, "post_id="+thread.PostId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = thread
+		}
+	})
+}
+
+func (s SqlThreadStore) Get(postId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var thread model.Thread
+		if err := s.GetReplica().SelectOne(&thread, "SELECT * FROM Threads WHERE PostId = :PostId", map[string]interface{}{"PostId": postId}); err != nil {
+			if err == sql.ErrNoRows {
+				result.Err = model.NewAppError("SqlThreadStore.Get", "store.sql_thread.get.missing.app_error", nil, "post_id="+postId, http.StatusNotFound)
+			} else {
+				result.Err = model.NewAppError("SqlThreadStore.Get", "store.sql_thread.get.app_error", nil, "post_id="+postId+", "+err.Error(), http.StatusInternalServerError)
+			}
+		} else {
+			result.Data = &thread
+		}
+	})
+}
+
+func (s SqlThreadStore) Delete(postId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		transaction, err := s.GetMaster().Begin()
+		if err != nil {
+			result.Err = model.NewAppError("SqlThreadStore.Delete", "store.sql_thread.delete.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		props := map[string]interface{}{"PostId": postId}
+
+		if _, err := transaction.Exec("DELETE FROM ThreadMemberships WHERE PostId = :PostId", props); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlThreadStore.Delete", "store.sql_thread.delete.app_error", nil, "post_id="+postId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := transaction.Exec("DELETE FROM Threads WHERE PostId = :PostId", props); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlThreadStore.Delete", "store.sql_thread.delete.app_error", nil, "post_id="+postId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlThreadStore.Delete", "store.sql_thread.delete.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+func (s SqlThreadStore) SaveMembership(membership *model.ThreadMembership) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if result.Err = membership.IsValid(); result.Err != nil {
+			return
+		}
+
+		if err := s.GetMaster().Insert(membership); err != nil {
+			if IsUniqueConstraintError(err, []string{"PostId", "threadmemberships_pkey", "PRIMARY"}) {
+				result.Err = model.NewAppError("SqlThreadStore.SaveMembership", "store.sql_thread.save_membership.exists.app_error", nil, "post_id="+membership.PostId+", user_id="+membership.UserId+", "+err.Error(), http.StatusBadRequest)
+			} else {
+				result.Err = model.NewAppError("SqlThreadStore.SaveMembership", "store.sql_thread.save_membership.app_error", nil, "post_id="+membership.PostId+", user_id="+membership.UserId+", "+err.Error(), http.StatusInternalServerError)
+			}
+		} else {
+			result.Data = membership
+		}
+	})
+}
+
+func (s SqlThreadStore) UpdateMembership(membership *model.ThreadMembership) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if result.Err = membership.IsValid(); result.Err != nil {
+			return
+		}
+
+		if _, err := s.GetMaster().Update(membership); err != nil {
+			result.Err = model.NewAppError("SqlThreadStore.UpdateMembership", "store.sql_thread.update_membership.app_error", nil, "post_id="+membership.PostId+", user_id="+membership.UserId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = membership
+		}
+	})
+}
+
+func (s SqlThreadStore) GetMembershipForUser(userId string, postId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var membership model.ThreadMembership
+		if err := s.GetMaster().SelectOne(&membership, "SELECT * FROM ThreadMemberships WHERE PostId = :PostId AND UserId = :UserId", map[string]interface{}{"PostId": postId, "UserId": userId}); err != nil {
+			if err == sql.ErrNoRows {
+				result.Err = model.NewAppError("SqlThreadStore.GetMembershipForUser", "store.sql_thread.get_membership.missing.app_error", nil, "post_id="+postId+", user_id="+userId, http.StatusNotFound)
+			} else {
+				result.Err = model.NewAppError("SqlThreadStore.GetMembershipForUser", "store.sql_thread.get_membership.app_error", nil, "post_id="+postId+", user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+			}
+		} else {
+			result.Data = &membership
+		}
+	})
+}
+
+func (s SqlThreadStore) GetMembershipsForThread(postId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var memberships []*model.ThreadMembership
+		if _, err := s.GetMaster().Select(&memberships, "SELECT * FROM ThreadMemberships WHERE PostId = :PostId", map[string]interface{}{"PostId": postId}); err != nil {
+			result.Err = model.NewAppError("SqlThreadStore.GetMembershipsForThread", "store.sql_thread.get_membership.app_error", nil, "post_id="+postId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = memberships
+		}
+	})
+}
+
+// DeleteMembershipsForUserInChannel removes the user from every thread in the channel, such as once
+// they've left it.
+func (s SqlThreadStore) DeleteMembershipsForUserInChannel(userId string, channelId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if _, err := s.GetMaster().Exec(
+			`DELETE FROM
+				ThreadMemberships
+			WHERE
+				UserId = :UserId
+				AND PostId IN (
+					SELECT
+						PostId
+					FROM
+						Threads
+					WHERE
+						ChannelId = :ChannelId
+				)`, map[string]interface{}{"UserId": userId, "ChannelId": channelId}); err != nil {
+			result.Err = model.NewAppError("SqlThreadStore.DeleteMembershipsForUserInChannel", "store.sql_thread.delete_memberships_for_user_in_channel.app_error", nil, "user_id="+userId+", channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// MarkAllAsReadForUser marks the threads that the user is a member of in the team, or in every team if teamId is
+// empty, as read up to the given time.
+func (s SqlThreadStore) MarkAllAsReadForUser(userId string, teamId string, timestamp int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		teamQuery := ""
+		if teamId != "" {
+			teamQuery = `
+				AND PostId IN (
+					SELECT
+						Threads.PostId
+					FROM
+						Threads, Channels
+					WHERE
+						Channels.Id = Threads.ChannelId
+						AND (Channels.TeamId = :TeamId OR Channels.TeamId = '')
+				)`
+		}
+
+		if _, err := s.GetMaster().Exec(
+			`UPDATE
+				ThreadMemberships
+			SET
+				LastViewed = :Timestamp,
+				UnreadMentions = 0,
+				LastUpdated = :LastUpdated
+			WHERE
+				UserId = :UserId
+				AND (LastViewed < :Timestamp OR UnreadMentions > 0)`+teamQuery, map[string]interface{}{"UserId": userId, "TeamId": teamId, "Timestamp": timestamp, "LastUpdated": model.GetMillis()}); err != nil {
+			result.Err = model.NewAppError("SqlThreadStore.MarkAllAsReadForUser", "store.sql_thread.mark_all_as_read_for_user.app_error", nil, "user_id="+userId+", team_id="+teamId+", "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+func (s SqlThreadStore) IncrementMentionCount(userId string, postId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if _, err := s.GetMaster().Exec(
+			`UPDATE
+				ThreadMemberships
+			SET
+				UnreadMentions = UnreadMentions + 1,
+				LastUpdated = :LastUpdated
+			WHERE
+				PostId = :PostId
+				AND UserId = :UserId`, map[string]interface{}{"PostId": postId, "UserId": userId, "LastUpdated": model.GetMillis()}); err != nil {
+			result.Err = model.NewAppError("SqlThreadStore.IncrementMentionCount", "store.sql_thread.increment_mention_count.app_error", nil, "post_id="+postId+", user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+const threadResponseColumns = `
+	Threads.PostId,
+	Threads.ChannelId,
+	Threads.ReplyCount,
+	Threads.LastReplyAt,
+	Threads.Participants,
+	ThreadMemberships.LastViewed AS LastViewedAt,
+	ThreadMemberships.UnreadMentions,
+	(SELECT
+		COUNT(*)
+	FROM
+		Posts
+	WHERE
+		Posts.RootId = Threads.PostId
+		AND Posts.CreateAt > ThreadMemberships.LastViewed
+		AND Posts.DeleteAt = 0) AS UnreadReplies`
+
+func (s SqlThreadStore) GetThreadForUser(userId string, postId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var thread model.ThreadResponse
+		if err := s.GetReplica().SelectOne(&thread,
+			`SELECT `+threadResponseColumns+`
+			FROM
+				Threads
+				INNER JOIN ThreadMemberships ON ThreadMemberships.PostId = Threads.PostId
+				INNER JOIN ChannelMembers ON ChannelMembers.ChannelId = Threads.ChannelId AND ChannelMembers.UserId = ThreadMemberships.UserId
+			WHERE
+				Threads.PostId = :PostId
+				AND ThreadMemberships.UserId = :UserId`, map[string]interface{}{"PostId": postId, "UserId": userId}); err != nil {
+			if err == sql.ErrNoRows {
+				result.Err = model.NewAppError("SqlThreadStore.GetThreadForUser", "store.sql_thread.get_membership.missing.app_error", nil, "post_id="+postId+", user_id="+userId, http.StatusNotFound)
+			} else {
+				result.Err = model.NewAppError("SqlThreadStore.GetThreadForUser", "store.sql_thread.get.app_error", nil, "post_id="+postId+", user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+			}
+		} else {
+			result.Data = &thread
+		}
+	})
+}
+
+// GetThreadsForUser returns the threads in a team that a user is following, with the most recently
+// active first. DMs and group messages are included regardless of the team. Threads in channels that
+// the user is no longer a member of are left out.
+func (s SqlThreadStore) GetThreadsForUser(userId string, teamId string, offset int, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		props := map[string]interface{}{"UserId": userId, "TeamId": teamId, "Offset": offset, "Limit": limit}
+
+		fromQuery := `
+			FROM
+				Threads
+				INNER JOIN ThreadMemberships ON ThreadMemberships.PostId = Threads.PostId
+				INNER JOIN Channels ON Channels.Id = Threads.ChannelId
+				INNER JOIN ChannelMembers ON ChannelMembers.ChannelId = Threads.ChannelId AND ChannelMembers.UserId = ThreadMemberships.UserId
+			WHERE
+				ThreadMemberships.UserId = :UserId
+				AND ThreadMemberships.Following = true
+				AND (Channels.TeamId = :TeamId OR Channels.TeamId = '')
+				AND Channels.DeleteAt = 0`
+
+		var totals struct {
+			Total               int64
+			TotalUnreadThreads  int64
+			TotalUnreadMentions int64
+		}
+		if err := s.GetReplica().SelectOne(&totals,
+			`SELECT
+				COUNT(*) AS Total,
+				COALESCE(SUM(CASE WHEN Threads.LastReplyAt > ThreadMemberships.LastViewed THEN 1 ELSE 0 END), 0) AS TotalUnreadThreads,
+				COALESCE(SUM(ThreadMemberships.UnreadMentions), 0) AS TotalUnreadMentions`+fromQuery, props); err != nil {
+			result.Err = model.NewAppError("SqlThreadStore.GetThreadsForUser", "store.sql_thread.get_threads_for_user.app_error", nil, "user_id="+userId+", team_id="+teamId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		threads := []*model.ThreadResponse{}
+		if _, err := s.GetReplica().Select(&threads,
+			`SELECT `+threadResponseColumns+fromQuery+`
+			ORDER BY
+				Threads.LastReplyAt DESC
+			LIMIT :Limit
+			OFFSET :Offset`, props); err != nil {
+			result.Err = model.NewAppError("SqlThreadStore.GetThreadsForUser", "store.sql_thread.get_threads_for_user.app_error", nil, "user_id="+userId+", team_id="+teamId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = &model.Threads{
+			Total:               totals.Total,
+			TotalUnreadThreads:  totals.TotalUnreadThreads,
+			TotalUnreadMentions: totals.TotalUnreadMentions,
+			Threads:             threads,
+		}
+	})
+}
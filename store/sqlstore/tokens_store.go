@@ -23,7 +23,7 @@ func NewSqlTokenStore(sqlStore SqlStore) store.TokenStore {
 		table := db.AddTableWithName(model.Token{}, "Tokens").SetKeys(false, "Token")
 		table.ColMap("Token").SetMaxSize(64)
 		table.ColMap("Type").SetMaxSize(64)
-		table.ColMap("Extra").SetMaxSize(128)
+		table.ColMap("Extra").SetMaxSize(model.TOKEN_EXTRA_MAX_SIZE)
 	}
 
 	return s
@@ -68,6 +68,19 @@ func (s SqlTokenStore) GetByToken(tokenString string) store.StoreChannel {
 	})
 }
 
+func (s SqlTokenStore) GetAllTokensByType(tokenType string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var tokens []*model.Token
+
+		if _, err := s.GetReplica().Select(&tokens, "SELECT * FROM Tokens WHERE Type = :Type", map[string]interface{}{"Type": tokenType}); err != nil {
+			result.Err = model.NewAppError("SqlTokenStore.GetAllTokensByType", "store.sql_recover.get_all_tokens_by_type.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = tokens
+	})
+}
+
 func (s SqlTokenStore) Cleanup() {
 	mlog.Debug("Cleaning up token store.")
 	deltime := model.GetMillis() - model.MAX_TOKEN_EXIPRY_TIME
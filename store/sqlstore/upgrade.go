@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -44,6 +45,9 @@ const (
 	OLDEST_SUPPORTED_VERSION = VERSION_3_0_0
 )
 
+const CHANNEL_MEMBER_COUNTS_BACKFILL_BATCH_SIZE = 1000
+const TEAM_MEMBER_COUNTS_BACKFILL_BATCH_SIZE = 1000
+
 const (
 	EXIT_VERSION_SAVE_MISSING = 1001
 	EXIT_TOO_OLD              = 1002
@@ -428,7 +432,124 @@ func UpgradeDatabaseToVersion50(sqlStore SqlStore) {
 	// TODO: Uncomment following condition when version 3.10.0 is released
 	//if shouldPerformUpgrade(sqlStore, VERSION_4_10_0, VERSION_5_0_0) {
 	sqlStore.CreateColumnIfNotExists("IncomingWebhooks", "ChannelLocked", "boolean", "boolean", "0")
+	sqlStore.CreateColumnIfNotExists("Channels", "DisableGroupMentions", "boolean", "boolean", "0")
+	sqlStore.CreateColumnIfNotExists("Channels", "DisableChannelMentions", "boolean", "boolean", "0")
+	sqlStore.CreateColumnIfNotExists("Channels", "JoinLeaveMessages", "varchar(8)", "varchar(8)", "")
+	sqlStore.CreateColumnIfNotExists("Channels", "EnableFeed", "boolean", "boolean", "0")
+	sqlStore.CreateColumnIfNotExists("Channels", "FeedKey", "varchar(26)", "varchar(26)", "")
+	sqlStore.CreateColumnIfNotExists("Channels", "DisableCustomCommands", "boolean", "boolean", "0")
+	sqlStore.CreateColumnIfNotExists("Channels", "DisabledCommandTriggers", "varchar(1024)", "varchar(1024)", "[]")
+	sqlStore.CreateColumnIfNotExists("Channels", "DisableReactions", "boolean", "boolean", "0")
+	sqlStore.CreateColumnIfNotExists("Channels", "IconEmojiName", "varchar(64)", "varchar(64)", "")
+	sqlStore.CreateColumnIfNotExists("Channels", "LastIconUpdate", "bigint", "bigint", "0")
+	sqlStore.CreateColumnIfNotExists("Channels", "PermanentDeleteAt", "bigint", "bigint", "0")
+	sqlStore.CreateColumnIfNotExists("IncomingWebhooks", "AllowedChannelIds", "varchar(1024)", "varchar(1024)", "[]")
+	sqlStore.CreateColumnIfNotExists("OutgoingWebhooks", "SigningSecret", "varchar(64)", "varchar(64)", "")
+	sqlStore.CreateColumnIfNotExists("OutgoingWebhooks", "SigningAlgorithm", "varchar(16)", "varchar(16)", "")
+	sqlStore.CreateColumnIfNotExists("OutgoingWebhooks", "DisableLegacyToken", "boolean", "boolean", "0")
+	sqlStore.CreateColumnIfNotExists("Commands", "SigningSecret", "varchar(64)", "varchar(64)", "")
+	sqlStore.CreateColumnIfNotExists("Commands", "SigningAlgorithm", "varchar(16)", "varchar(16)", "")
+	sqlStore.CreateColumnIfNotExists("Commands", "DisableLegacyToken", "boolean", "boolean", "0")
+	sqlStore.CreateColumnIfNotExists("ChannelMembers", "LastReadPostId", "varchar(26)", "varchar(26)", "")
+	sqlStore.CreateColumnIfNotExists("ChannelMembers", "LastReadPostAt", "bigint", "bigint", "0")
+	sqlStore.CreateColumnIfNotExists("Users", "DeactivateAt", "bigint", "bigint", "0")
+	sqlStore.CreateColumnIfNotExists("Users", "IsBot", "boolean", "boolean", "0")
+	sqlStore.CreateColumnIfNotExists("Users", "LastSeenAt", "bigint", "bigint", "0")
+	sqlStore.CreateColumnIfNotExists("Teams", "WelcomeMessage", "varchar(4000)", "varchar(4000)", "")
+	sqlStore.CreateColumnIfNotExists("Teams", "InviteIdDisabled", "boolean", "boolean", "0")
+	sqlStore.CreateColumnIfNotExists("Sessions", "IsImpersonation", "boolean", "boolean", "0")
+	sqlStore.CreateColumnIfNotExists("ChannelMemberHistory", "JoinActorId", "varchar(26)", "varchar(26)", "")
+	sqlStore.CreateColumnIfNotExists("ChannelMemberHistory", "LeaveActorId", "varchar(26)", "varchar(26)", "")
+	sqlStore.CreateColumnIfNotExists("ChannelMemberHistory", "LeaveReason", "varchar(32)", "varchar(32)", "")
+	sqlStore.CreateColumnIfNotExists("OAuthAccessData", "PreviousRefreshToken", "varchar(26)", "varchar(26)", "")
+	sqlStore.CreateColumnIfNotExists("OAuthApps", "AccessTokenExpiresIn", "bigint", "bigint", "0")
+	sqlStore.CreateColumnIfNotExists("FileInfo", "HasPreviewText", "boolean", "boolean", "0")
+	sqlStore.CreateColumnIfNotExists("FileInfo", "Language", "varchar(32)", "varchar(32)", "")
+	sqlStore.CreateColumnIfNotExists("FileInfo", "ChannelId", "varchar(26)", "varchar(26)", "")
+	sqlStore.CreateColumnIfNotExists("FileInfo", "MissingAt", "bigint", "bigint", "0")
+	sqlStore.CreateColumnIfNotExists("FileInfo", "ContentType", "varchar(256)", "varchar(256)", "")
+	sqlStore.CreateColumnIfNotExists("FileInfo", "Hash", "varchar(64)", "varchar(64)", "")
+	// apps from before refresh tokens were rotated keep the old behaviour
+	sqlStore.CreateColumnIfNotExists("OAuthApps", "LegacyRefreshTokens", "boolean", "boolean", "1")
+	// channels moderated before guests could be moderated separately withheld the permissions from guests as well
+	sqlStore.CreateColumnIfNotExists("ChannelModerations", "Guests", "boolean", "boolean", "1")
+	sqlStore.CreateColumnIfNotExists("ChannelModerations", "Members", "boolean", "boolean", "1")
+	if !sqlStore.DoesColumnExist("Channels", "MemberCount") {
+		sqlStore.CreateColumnIfNotExists("Channels", "MemberCount", "bigint", "bigint", "0")
+		sqlStore.CreateColumnIfNotExists("Channels", "GuestCount", "bigint", "bigint", "0")
+		backfillChannelMemberCounts(sqlStore)
+	}
+	if !sqlStore.DoesColumnExist("Teams", "MemberCount") {
+		sqlStore.CreateColumnIfNotExists("Teams", "MemberCount", "bigint", "bigint", "0")
+		backfillTeamMemberCounts(sqlStore)
+	}
+	widenChannelHeaderAndPurpose(sqlStore)
+	widenColumn(sqlStore, "Tokens", "Extra", model.TOKEN_EXTRA_MAX_SIZE)
 
 	//	saveSchemaVersion(sqlStore, VERSION_5_0_0)
 	//}
 }
+
+// widenChannelHeaderAndPurpose makes the Header and Purpose columns of Channels long enough for the longest ones
+// that the server allows.
+func widenChannelHeaderAndPurpose(sqlStore SqlStore) {
+	widenColumn(sqlStore, "Channels", "Header", model.CHANNEL_HEADER_MAX_RUNES)
+	widenColumn(sqlStore, "Channels", "Purpose", model.CHANNEL_PURPOSE_MAX_RUNES)
+}
+
+// widenColumn makes a varchar column at least maxLength long. Its length is checked first so that the table isn't
+// altered every time that the server starts.
+func widenColumn(sqlStore SqlStore, tableName string, columnName string, maxLength int) {
+	length, err := strconv.Atoi(sqlStore.GetMaxLengthOfColumnIfExists(tableName, columnName))
+	if err != nil || length >= maxLength {
+		return
+	}
+
+	colType := "varchar(" + strconv.Itoa(maxLength) + ")"
+	sqlStore.AlterColumnTypeIfExists(tableName, columnName, colType, colType)
+}
+
+// backfillChannelMemberCounts counts the members and guests of the existing channels a batch at a time so that
+// large servers don't lock every channel at once. Any channels left uncounted, such as if the server is stopped
+// part way through, are fixed by the job that reconciles the counts.
+func backfillChannelMemberCounts(sqlStore SqlStore) {
+	mlog.Info("Counting the members of existing channels")
+
+	afterChannelId := ""
+	for {
+		result := <-sqlStore.Channel().ReconcileMemberCounts(afterChannelId, CHANNEL_MEMBER_COUNTS_BACKFILL_BATCH_SIZE)
+		if result.Err != nil {
+			mlog.Error(fmt.Sprintf("Failed to count the members of existing channels err=%v", result.Err))
+			return
+		}
+
+		batch := result.Data.(*model.ChannelMemberCountsBatch)
+		if batch.LastChannelId == "" {
+			return
+		}
+
+		afterChannelId = batch.LastChannelId
+	}
+}
+
+// backfillTeamMemberCounts counts the active members of the existing teams a batch at a time, the same way as
+// backfillChannelMemberCounts does for channels.
+func backfillTeamMemberCounts(sqlStore SqlStore) {
+	mlog.Info("Counting the members of existing teams")
+
+	afterTeamId := ""
+	for {
+		result := <-sqlStore.Team().ReconcileMemberCounts(afterTeamId, TEAM_MEMBER_COUNTS_BACKFILL_BATCH_SIZE)
+		if result.Err != nil {
+			mlog.Error(fmt.Sprintf("Failed to count the members of existing teams err=%v", result.Err))
+			return
+		}
+
+		batch := result.Data.(*model.TeamMemberCountsBatch)
+		if batch.LastTeamId == "" {
+			return
+		}
+
+		afterTeamId = batch.LastTeamId
+	}
+}
@@ -4,8 +4,13 @@
 package sqlstore
 
 import (
+	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/store"
 )
@@ -39,3 +44,45 @@ func TestSaveSchemaVersion(t *testing.T) {
 		saveSchemaVersion(ss.(*store.LayeredStore).DatabaseLayer.(SqlStore), model.CurrentVersion)
 	})
 }
+
+func TestUpgradeWidensChannelHeaderAndPurpose(t *testing.T) {
+	StoreTest(t, func(t *testing.T, ss store.Store) {
+		sqlStore := ss.(*store.LayeredStore).DatabaseLayer.(SqlStore)
+
+		// Put the columns back to how they were before they were widened
+		sqlStore.AlterColumnTypeIfExists("Channels", "Header", "varchar(1024)", "varchar(1024)")
+		sqlStore.AlterColumnTypeIfExists("Channels", "Purpose", "varchar(250)", "varchar(250)")
+		require.Equal(t, "1024", sqlStore.GetMaxLengthOfColumnIfExists("Channels", "Header"))
+		require.Equal(t, "250", sqlStore.GetMaxLengthOfColumnIfExists("Channels", "Purpose"))
+
+		header := strings.Repeat("h", 1000)
+		channel := store.Must(ss.Channel().Save(&model.Channel{
+			TeamId:      model.NewId(),
+			DisplayName: "Widened",
+			Name:        "zz" + model.NewId() + "b",
+			Type:        model.CHANNEL_OPEN,
+			Header:      header,
+		}, -1)).(*model.Channel)
+
+		UpgradeDatabaseToVersion50(sqlStore)
+
+		assert.Equal(t, strconv.Itoa(model.CHANNEL_HEADER_MAX_RUNES), sqlStore.GetMaxLengthOfColumnIfExists("Channels", "Header"))
+		assert.Equal(t, strconv.Itoa(model.CHANNEL_PURPOSE_MAX_RUNES), sqlStore.GetMaxLengthOfColumnIfExists("Channels", "Purpose"))
+
+		// Existing values are kept and the longest allowed values can be saved
+		saved := store.Must(ss.Channel().Get(channel.Id, false)).(*model.Channel)
+		assert.Equal(t, header, saved.Header)
+
+		saved.Header = strings.Repeat("é", model.CHANNEL_HEADER_MAX_RUNES)
+		saved.Purpose = strings.Repeat("é", model.CHANNEL_PURPOSE_MAX_RUNES)
+		store.Must(ss.Channel().Update(saved))
+
+		updated := store.Must(ss.Channel().Get(channel.Id, false)).(*model.Channel)
+		assert.Equal(t, saved.Header, updated.Header)
+		assert.Equal(t, saved.Purpose, updated.Purpose)
+
+		// Running it again leaves them as they are
+		UpgradeDatabaseToVersion50(sqlStore)
+		assert.Equal(t, strconv.Itoa(model.CHANNEL_HEADER_MAX_RUNES), sqlStore.GetMaxLengthOfColumnIfExists("Channels", "Header"))
+	})
+}
@@ -0,0 +1,233 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+type SqlUserAttributeStore struct {
+	SqlStore
+}
+
+func NewSqlUserAttributeStore(sqlStore SqlStore) store.UserAttributeStore {
+	s := &SqlUserAttributeStore{
+		SqlStore: sqlStore,
+	}
+
+	for _, db := range sqlStore.GetAllConns() {
+		tableFields := db.AddTableWithName(model.UserAttributeField{}, "UserAttributeFields").SetKeys(false, "Id")
+		tableFields.ColMap("Id").SetMaxSize(26)
+		tableFields.ColMap("Name").SetMaxSize(model.USER_ATTRIBUTE_NAME_MAX_RUNES).SetUnique(true)
+		tableFields.ColMap("Type").SetMaxSize(32)
+		tableFields.ColMap("Options").SetMaxSize(model.USER_ATTRIBUTE_OPTIONS_MAX_LENGTH)
+		tableFields.ColMap("Visibility").SetMaxSize(32)
+
+		tableValues := db.AddTableWithName(model.UserAttribute{}, "UserAttributes").SetKeys(false, "UserId", "FieldId")
+		tableValues.ColMap("UserId").SetMaxSize(26)
+		tableValues.ColMap("FieldId").SetMaxSize(26)
+		tableValues.ColMap("Value").SetMaxSize(model.USER_ATTRIBUTE_VALUE_MAX_RUNES)
+	}
+
+	return s
+}
+
+func (s SqlUserAttributeStore) CreateIndexesIfNotExists() {
+	s.CreateIndexIfNotExists("idx_userattributes_field_id", "UserAttributes", "FieldId")
+}
+
+func (s SqlUserAttributeStore) SaveField(field *model.UserAttributeField) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if len(field.Id) > 0 {
+			result.Err = model.NewAppError("SqlUserAttributeStore.SaveField", "store.sql_user_attribute.save_field.existing.app_error", nil, "id="+field.Id, http.StatusBadRequest)
+			return
+		}
+
+		field.PreSave()
+		if result.Err = field.IsValid(); result.Err != nil {
+			return
+		}
+
+		if err := s.GetMaster().Insert(field); err != nil {
+			if IsUniqueConstraintError(err, []string{"Name", "userattributefields_name_key"}) {
+				result.Err = model.NewAppError("SqlUserAttributeStore.SaveField", "store.sql_user_attribute.save_field.name_exists.app_error", nil, "name="+field.Name+", "+err.Error(), http.StatusBadRequest)
+			} else {
+				result.Err = model.NewAppError("SqlUserAttributeStore.SaveField", "store.sql_user_attribute.save_field.app_error", nil, "id="+field.Id+", "+err.Error(), http.StatusInternalServerError)
+			}
+		} else {
+			result.Data = field
+		}
+	})
+}
+
+func (s SqlUserAttributeStore) UpdateField(field *model.UserAttributeField) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		field.PreUpdate()
+		if result.Err = field.IsValid(); result.Err != nil {
+			return
+		}
+
+		if count, err := s.GetMaster().Update(field); err != nil {
+			if IsUniqueConstraintError(err, []string{"Name", "userattributefields_name_key"}) {
+				result.Err = model.NewAppError("SqlUserAttributeStore.UpdateField", "store.sql_user_attribute.save_field.name_exists.app_error", nil, "name="+field.Name+", "+err.Error(), http.StatusBadRequest)
+			} else {
+				result.Err = model.NewAppError("SqlUserAttributeStore.UpdateField", "store.sql_user_attribute.update_field.app_error", nil, "id="+field.Id+", "+err.Error(), http.StatusInternalServerError)
+			}
+		} else if count != 1 {
+			result.Err = model.NewAppError("SqlUserAttributeStore.UpdateField", "store.sql_user_attribute.get_field.missing.app_error", nil, "id="+field.Id, http.StatusNotFound)
+		} else {
+			result.Data = field
+		}
+	})
+}
+
+func (s SqlUserAttributeStore) GetField(fieldId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var field model.UserAttributeField
+		if err := s.GetReplica().SelectOne(&field, "SELECT * FROM UserAttributeFields WHERE Id = :Id", map[string]interface{}{"Id": fieldId}); err != nil {
+			if err == sql.ErrNoRows {
+				result.Err = model.NewAppError("SqlUserAttributeStore.GetField", "store.sql_user_attribute.get_field.missing.app_error", nil, "id="+fieldId, http.StatusNotFound)
+			} else {
+				result.Err = model.NewAppError("SqlUserAttributeStore.GetField", "store.sql_user_attribute.get_field.app_error", nil, "id="+fieldId+", "+err.Error(), http.StatusInternalServerError)
+			}
+		} else {
+			result.Data = &field
+		}
+	})
+}
+
+// GetFields returns every field in the order that they should be shown on profiles.
+func (s SqlUserAttributeStore) GetFields() store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		fields := []*model.UserAttributeField{}
+		if _, err := s.GetReplica().Select(&fields, "SELECT * FROM UserAttributeFields ORDER BY SortOrder, Name"); err != nil {
+			result.Err = model.NewAppError("SqlUserAttributeStore.GetFields", "store.sql_user_attribute.get_field.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = fields
+		}
+	})
+}
+
+// DeleteField deletes the field along with every user's value for it.
+func (s SqlUserAttributeStore) DeleteField(fieldId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		transaction, err := s.GetMaster().Begin()
+		if err != nil {
+			result.Err = model.NewAppError("SqlUserAttributeStore.DeleteField", "store.sql_user_attribute.delete_field.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		props := map[string]interface{}{"FieldId": fieldId}
+
+		if _, err := transaction.Exec("DELETE FROM UserAttributes WHERE FieldId = :FieldId", props); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlUserAttributeStore.DeleteField", "store.sql_user_attribute.delete_field.app_error", nil, "id="+fieldId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := transaction.Exec("DELETE FROM UserAttributeFields WHERE Id = :FieldId", props); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlUserAttributeStore.DeleteField", "store.sql_user_attribute.delete_field.app_error", nil, "id="+fieldId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlUserAttributeStore.DeleteField", "store.sql_user_attribute.delete_field.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// SaveValue sets the user's value for a field, replacing any value that they already had.
+func (s SqlUserAttributeStore) SaveValue(attribute *model.UserAttribute) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		attribute.PreSave()
+		if result.Err = attribute.IsValid(); result.Err != nil {
+			return
+		}
+
+		if count, err := s.GetMaster().Update(attribute); err != nil {
+			result.Err = model.NewAppError("SqlUserAttributeStore.SaveValue", "store.sql_user_attribute.save_value.app_error", nil, "user_id="+attribute.UserId+", field_id="+attribute.FieldId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		} else if count == 0 {
+			// The user didn't have a value yet, so one needs to be inserted instead. If the insert fails because one
+			// was inserted in the meantime, the other request won the race and that value is kept.
+			if err := s.GetMaster().Insert(attribute); err != nil && !IsUniqueConstraintError(err, []string{"PRIMARY", "userattributes_pkey"}) {
+				result.Err = model.NewAppError("SqlUserAttributeStore.SaveValue", "store.sql_user_attribute.save_value.app_error", nil, "user_id="+attribute.UserId+", field_id="+attribute.FieldId+", "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		result.Data = attribute
+	})
+}
+
+func (s SqlUserAttributeStore) DeleteValue(userId string, fieldId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if _, err := s.GetMaster().Exec("DELETE FROM UserAttributes WHERE UserId = :UserId AND FieldId = :FieldId", map[string]interface{}{"UserId": userId, "FieldId": fieldId}); err != nil {
+			result.Err = model.NewAppError("SqlUserAttributeStore.DeleteValue", "store.sql_user_attribute.delete_value.app_error", nil, "user_id="+userId+", field_id="+fieldId+", "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+func (s SqlUserAttributeStore) GetValuesForUser(userId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		attributes := []*model.UserAttribute{}
+		if _, err := s.GetReplica().Select(&attributes, "SELECT * FROM UserAttributes WHERE UserId = :UserId", map[string]interface{}{"UserId": userId}); err != nil {
+			result.Err = model.NewAppError("SqlUserAttributeStore.GetValuesForUser", "store.sql_user_attribute.get_values.app_error", nil, "user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = attributes
+		}
+	})
+}
+
+func (s SqlUserAttributeStore) GetValuesForUsers(userIds []string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		attributes := []*model.UserAttribute{}
+		if len(userIds) == 0 {
+			result.Data = attributes
+			return
+		}
+
+		props := make(map[string]interface{})
+		idQuery := ""
+		for i, userId := range userIds {
+			if len(idQuery) > 0 {
+				idQuery += ", "
+			}
+
+			props["userId"+strconv.Itoa(i)] = userId
+			idQuery += ":userId" + strconv.Itoa(i)
+		}
+
+		if _, err := s.GetReplica().Select(&attributes, "SELECT * FROM UserAttributes WHERE UserId IN ("+idQuery+")", props); err != nil {
+			result.Err = model.NewAppError("SqlUserAttributeStore.GetValuesForUsers", "store.sql_user_attribute.get_values.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = attributes
+		}
+	})
+}
+
+func (s SqlUserAttributeStore) GetValuesForField(fieldId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		attributes := []*model.UserAttribute{}
+		if _, err := s.GetReplica().Select(&attributes, "SELECT * FROM UserAttributes WHERE FieldId = :FieldId", map[string]interface{}{"FieldId": fieldId}); err != nil {
+			result.Err = model.NewAppError("SqlUserAttributeStore.GetValuesForField", "store.sql_user_attribute.get_values.app_error", nil, "field_id="+fieldId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = attributes
+		}
+	})
+}
+
+func (s SqlUserAttributeStore) PermanentDeleteByUser(userId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if _, err := s.GetMaster().Exec("DELETE FROM UserAttributes WHERE UserId = :UserId", map[string]interface{}{"UserId": userId}); err != nil {
+			result.Err = model.NewAppError("SqlUserAttributeStore.PermanentDeleteByUser", "store.sql_user_attribute.delete_value.app_error", nil, "user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -19,8 +19,6 @@ import (
 const (
 	PROFILES_IN_CHANNEL_CACHE_SIZE = model.CHANNEL_CACHE_SIZE
 	PROFILES_IN_CHANNEL_CACHE_SEC  = 900 // 15 mins
-	PROFILE_BY_IDS_CACHE_SIZE      = model.SESSION_CACHE_SIZE
-	PROFILE_BY_IDS_CACHE_SEC       = 900 // 15 mins
 )
 
 var (
@@ -33,33 +31,30 @@ var (
 type SqlUserStore struct {
 	SqlStore
 	metrics einterfaces.MetricsInterface
+
+	profileByIdsCache *utils.Cache
 }
 
 var profilesInChannelCache *utils.Cache = utils.NewLru(PROFILES_IN_CHANNEL_CACHE_SIZE)
-var profileByIdsCache *utils.Cache = utils.NewLru(PROFILE_BY_IDS_CACHE_SIZE)
 
 func (us SqlUserStore) ClearCaches() {
 	profilesInChannelCache.Purge()
-	profileByIdsCache.Purge()
+	us.GetCacheProvider().InvalidateSkipClusterSend(model.CACHE_PROFILE_BY_IDS, utils.CACHE_PURGE_KEY)
 
 	if us.metrics != nil {
 		us.metrics.IncrementMemCacheInvalidationCounter("Profiles in Channel - Purge")
-		us.metrics.IncrementMemCacheInvalidationCounter("Profile By Ids - Purge")
 	}
 }
 
 func (us SqlUserStore) InvalidatProfileCacheForUser(userId string) {
-	profileByIdsCache.Remove(userId)
-
-	if us.metrics != nil {
-		us.metrics.IncrementMemCacheInvalidationCounter("Profile By Ids - Remove")
-	}
+	us.GetCacheProvider().InvalidateSkipClusterSend(model.CACHE_PROFILE_BY_IDS, userId)
 }
 
 func NewSqlUserStore(sqlStore SqlStore, metrics einterfaces.MetricsInterface) store.UserStore {
 	us := &SqlUserStore{
-		SqlStore: sqlStore,
-		metrics:  metrics,
+		SqlStore:          sqlStore,
+		metrics:           metrics,
+		profileByIdsCache: sqlStore.GetCacheProvider().GetCache(model.CACHE_PROFILE_BY_IDS),
 	}
 
 	for _, db := range sqlStore.GetAllConns() {
@@ -80,6 +75,10 @@ func NewSqlUserStore(sqlStore SqlStore, metrics einterfaces.MetricsInterface) st
 		table.ColMap("MfaSecret").SetMaxSize(128)
 		table.ColMap("Position").SetMaxSize(128)
 		table.ColMap("Timezone").SetMaxSize(256)
+
+		tableuh := db.AddTableWithName(model.UsernameHistory{}, "UsernameHistory").SetKeys(false, "Username")
+		tableuh.ColMap("Username").SetMaxSize(64)
+		tableuh.ColMap("UserId").SetMaxSize(26)
 	}
 
 	return us
@@ -90,6 +89,9 @@ func (us SqlUserStore) CreateIndexesIfNotExists() {
 	us.CreateIndexIfNotExists("idx_users_update_at", "Users", "UpdateAt")
 	us.CreateIndexIfNotExists("idx_users_create_at", "Users", "CreateAt")
 	us.CreateIndexIfNotExists("idx_users_delete_at", "Users", "DeleteAt")
+	us.CreateIndexIfNotExists("idx_users_auth_service", "Users", "AuthService")
+	us.CreateIndexIfNotExists("idx_users_last_seen_at", "Users", "LastSeenAt")
+	us.CreateIndexIfNotExists("idx_usernamehistory_user_id", "UsernameHistory", "UserId")
 
 	if us.DriverName() == model.DATABASE_DRIVER_POSTGRES {
 		us.CreateIndexIfNotExists("idx_users_email_lower", "Users", "lower(Email)")
@@ -97,6 +99,19 @@ func (us SqlUserStore) CreateIndexesIfNotExists() {
 		us.CreateIndexIfNotExists("idx_users_nickname_lower", "Users", "lower(Nickname)")
 		us.CreateIndexIfNotExists("idx_users_firstname_lower", "Users", "lower(FirstName)")
 		us.CreateIndexIfNotExists("idx_users_lastname_lower", "Users", "lower(LastName)")
+
+		// Plain indexes can't be used for LIKE prefix matches unless the database uses the C locale
+		us.CreateIndexIfNotExists("idx_users_email_lower_textpattern", "Users", "lower(Email) text_pattern_ops")
+		us.CreateIndexIfNotExists("idx_users_username_lower_textpattern", "Users", "lower(Username) text_pattern_ops")
+		us.CreateIndexIfNotExists("idx_users_nickname_lower_textpattern", "Users", "lower(Nickname) text_pattern_ops")
+		us.CreateIndexIfNotExists("idx_users_firstname_lower_textpattern", "Users", "lower(FirstName) text_pattern_ops")
+		us.CreateIndexIfNotExists("idx_users_lastname_lower_textpattern", "Users", "lower(LastName) text_pattern_ops")
+	} else if us.DriverName() == model.DATABASE_DRIVER_MYSQL {
+		// MySQL's case insensitive collation lets prefix matches use these directly. Username and Email already have
+		// unique indexes.
+		us.CreateIndexIfNotExists("idx_users_nickname", "Users", "Nickname")
+		us.CreateIndexIfNotExists("idx_users_firstname", "Users", "FirstName")
+		us.CreateIndexIfNotExists("idx_users_lastname", "Users", "LastName")
 	}
 
 	us.CreateFullTextIndexIfNotExists("idx_users_all_txt", "Users", strings.Join(USER_SEARCH_TYPE_ALL, ", "))
@@ -155,10 +170,13 @@ func (us SqlUserStore) Update(user *model.User, trustedUpdateData bool) store.St
 			user.FailedAttempts = oldUser.FailedAttempts
 			user.MfaSecret = oldUser.MfaSecret
 			user.MfaActive = oldUser.MfaActive
+			user.IsBot = oldUser.IsBot
+			user.LastSeenAt = oldUser.LastSeenAt
 
 			if !trustedUpdateData {
 				user.Roles = oldUser.Roles
 				user.DeleteAt = oldUser.DeleteAt
+				user.DeactivateAt = oldUser.DeactivateAt
 			}
 
 			if user.IsOAuthUser() {
@@ -179,7 +197,15 @@ func (us SqlUserStore) Update(user *model.User, trustedUpdateData bool) store.St
 				user.UpdateMentionKeysFromUsername(oldUser.Username)
 			}
 
-			if count, err := us.GetMaster().Update(user); err != nil {
+			var count int64
+			var err error
+			if user.DeleteAt != oldUser.DeleteAt || user.IsGuest() != oldUser.IsGuest() {
+				count, err = us.updateAndRecountMembers(user)
+			} else {
+				count, err = us.GetMaster().Update(user)
+			}
+
+			if err != nil {
 				if IsUniqueConstraintError(err, []string{"Email", "users_email_key", "idx_users_email_unique"}) {
 					result.Err = model.NewAppError("SqlUserStore.Update", "store.sql_user.update.email_taken.app_error", nil, "user_id="+user.Id+", "+err.Error(), http.StatusBadRequest)
 				} else if IsUniqueConstraintError(err, []string{"Username", "users_username_key", "idx_users_username_unique"}) {
@@ -198,6 +224,49 @@ func (us SqlUserStore) Update(user *model.User, trustedUpdateData bool) store.St
 	})
 }
 
+// updateAndRecountMembers updates a user that's being deactivated, reactivated or having their guest role changed,
+// along with the member and guest counts of their channels and the member counts of their teams, in a single
+// transaction.
+func (us SqlUserStore) updateAndRecountMembers(user *model.User) (int64, error) {
+	transaction, err := us.GetMaster().Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	// The counts are taken away using the user as they were and then added back using them as they are now
+	if err := updateMemberCountsT(transaction, user.Id, "", -1); err != nil {
+		transaction.Rollback()
+		return 0, err
+	}
+
+	if err := updateTeamMemberCountsT(transaction, user.Id, "", -1); err != nil {
+		transaction.Rollback()
+		return 0, err
+	}
+
+	count, err := transaction.Update(user)
+	if err != nil {
+		transaction.Rollback()
+		return 0, err
+	}
+
+	if err := updateMemberCountsT(transaction, user.Id, "", 1); err != nil {
+		transaction.Rollback()
+		return 0, err
+	}
+
+	if err := updateTeamMemberCountsT(transaction, user.Id, "", 1); err != nil {
+		transaction.Rollback()
+		return 0, err
+	}
+
+	if err := transaction.Commit(); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
 func (us SqlUserStore) UpdateLastPictureUpdate(userId string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		curTime := model.GetMillis()
@@ -307,6 +376,44 @@ func (us SqlUserStore) UpdateMfaActive(userId string, active bool) store.StoreCh
 	})
 }
 
+func (us SqlUserStore) UpdateDeactivateAt(userId string, deactivateAt int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		updateAt := model.GetMillis()
+
+		if sqlResult, err := us.GetMaster().Exec("UPDATE Users SET DeactivateAt = :DeactivateAt, UpdateAt = :UpdateAt WHERE Id = :UserId AND DeleteAt = 0", map[string]interface{}{"DeactivateAt": deactivateAt, "UpdateAt": updateAt, "UserId": userId}); err != nil {
+			result.Err = model.NewAppError("SqlUserStore.UpdateDeactivateAt", "store.sql_user.update_deactivate_at.app_error", nil, "id="+userId+", "+err.Error(), http.StatusInternalServerError)
+		} else if rows, _ := sqlResult.RowsAffected(); rows != 1 {
+			result.Err = model.NewAppError("SqlUserStore.UpdateDeactivateAt", "store.sql_user.update_deactivate_at.app_error", nil, "id="+userId, http.StatusBadRequest)
+		} else {
+			result.Data = userId
+		}
+	})
+}
+
+// UpdateLastSeenAt records when the user was last active. It doesn't change UpdateAt since it's updated from
+// every session and doesn't change anything that's shown to other users.
+func (us SqlUserStore) UpdateLastSeenAt(userId string, lastSeenAt int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if _, err := us.GetMaster().Exec("UPDATE Users SET LastSeenAt = :LastSeenAt WHERE Id = :UserId AND LastSeenAt < :LastSeenAt", map[string]interface{}{"LastSeenAt": lastSeenAt, "UserId": userId}); err != nil {
+			result.Err = model.NewAppError("SqlUserStore.UpdateLastSeenAt", "store.sql_user.update_last_seen_at.app_error", nil, "user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = userId
+		}
+	})
+}
+
+func (us SqlUserStore) GetDueForDeactivation(deactivateBefore int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var users []*model.User
+
+		if _, err := us.GetMaster().Select(&users, "SELECT * FROM Users WHERE DeleteAt = 0 AND DeactivateAt > 0 AND DeactivateAt <= :DeactivateBefore ORDER BY DeactivateAt ASC", map[string]interface{}{"DeactivateBefore": deactivateBefore}); err != nil {
+			result.Err = model.NewAppError("SqlUserStore.GetDueForDeactivation", "store.sql_user.get_due_for_deactivation.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = users
+		}
+	})
+}
+
 func (us SqlUserStore) Get(id string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		if obj, err := us.GetReplica().Get(model.User{}, id); err != nil {
@@ -439,6 +546,38 @@ func (us SqlUserStore) GetProfilesInChannel(channelId string, offset int, limit
 	})
 }
 
+// GetBotsInChannel returns the active bot accounts that are members of a channel.
+func (us SqlUserStore) GetBotsInChannel(channelId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var users []*model.User
+
+		query := `
+				SELECT
+					Users.*
+				FROM
+					Users, ChannelMembers
+				WHERE
+					ChannelMembers.ChannelId = :ChannelId
+					AND Users.Id = ChannelMembers.UserId
+					AND Users.IsBot = true
+					AND Users.DeleteAt = 0
+				ORDER BY
+					Users.Username ASC
+		`
+
+		if _, err := us.GetReplica().Select(&users, query, map[string]interface{}{"ChannelId": channelId}); err != nil {
+			result.Err = model.NewAppError("SqlUserStore.GetBotsInChannel", "store.sql_user.get_bots_in_channel.app_error", nil, "channel_id="+channelId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		for _, u := range users {
+			u.Sanitize(map[string]bool{})
+		}
+
+		result.Data = users
+	})
+}
+
 func (us SqlUserStore) GetProfilesInChannelByStatus(channelId string, offset int, limit int) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		var users []*model.User
@@ -619,6 +758,40 @@ func (us SqlUserStore) GetProfilesByUsernames(usernames []string, teamId string)
 	})
 }
 
+func (us SqlUserStore) GetByFullNames(fullNames []string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		users := []*model.User{}
+		if len(fullNames) == 0 {
+			result.Data = users
+			return
+		}
+
+		props := make(map[string]interface{})
+		nameQuery := ""
+
+		for index, fullName := range fullNames {
+			if len(nameQuery) > 0 {
+				nameQuery += ", "
+			}
+
+			props["fullName"+strconv.Itoa(index)] = strings.ToLower(fullName)
+			nameQuery += ":fullName" + strconv.Itoa(index)
+		}
+
+		query := `SELECT * FROM Users WHERE DeleteAt = 0 AND LOWER(TRIM(CONCAT(FirstName, ' ', LastName))) IN (` + nameQuery + `)`
+
+		if _, err := us.GetReplica().Select(&users, query, props); err != nil {
+			result.Err = model.NewAppError("SqlUserStore.GetByFullNames", "store.sql_user.get_profiles.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			for _, u := range users {
+				u.Sanitize(map[string]bool{})
+			}
+
+			result.Data = users
+		}
+	})
+}
+
 type UserWithLastActivityAt struct {
 	model.User
 	LastActivityAt int64
@@ -689,7 +862,7 @@ func (us SqlUserStore) GetProfileByIds(userIds []string, allowFromCache bool) st
 
 		if allowFromCache {
 			for _, userId := range userIds {
-				if cacheItem, ok := profileByIdsCache.Get(userId); ok {
+				if cacheItem, ok := us.profileByIdsCache.Get(userId); ok {
 					u := &model.User{}
 					*u = *cacheItem.(*model.User)
 					users = append(users, u)
@@ -697,14 +870,10 @@ func (us SqlUserStore) GetProfileByIds(userIds []string, allowFromCache bool) st
 					remainingUserIds = append(remainingUserIds, userId)
 				}
 			}
-			if us.metrics != nil {
-				us.metrics.AddMemCacheHitCounter("Profile By Ids", float64(len(users)))
-				us.metrics.AddMemCacheMissCounter("Profile By Ids", float64(len(remainingUserIds)))
-			}
 		} else {
 			remainingUserIds = userIds
 			if us.metrics != nil {
-				us.metrics.AddMemCacheMissCounter("Profile By Ids", float64(len(remainingUserIds)))
+				us.metrics.AddMemCacheMissCounter(us.profileByIdsCache.Name(), float64(len(remainingUserIds)))
 			}
 		}
 
@@ -732,7 +901,7 @@ func (us SqlUserStore) GetProfileByIds(userIds []string, allowFromCache bool) st
 
 				cpy := &model.User{}
 				*cpy = *u
-				profileByIdsCache.AddWithExpiresInSecs(cpy.Id, cpy, PROFILE_BY_IDS_CACHE_SEC)
+				us.profileByIdsCache.AddWithDefaultExpires(cpy.Id, cpy)
 			}
 
 			result.Data = users
@@ -807,6 +976,52 @@ func (us SqlUserStore) GetAllUsingAuthService(authService string) store.StoreCha
 	})
 }
 
+// usingAuthServiceQuery matches the users, other than bots, that sign in with the auth service and, unless it's
+// empty, the given auth data.
+func usingAuthServiceQuery(authService string, authData string) (string, map[string]interface{}) {
+	query := "FROM Users WHERE AuthService = :AuthService AND IsBot = false"
+	params := map[string]interface{}{"AuthService": authService}
+
+	if authData != "" {
+		query += " AND AuthData = :AuthData"
+		params["AuthData"] = authData
+	}
+
+	return query, params
+}
+
+// GetUsingAuthService returns a page of the users who sign in with the auth service, ordered by when they were
+// created. Bots are never returned.
+func (us SqlUserStore) GetUsingAuthService(authService string, authData string, offset int, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		query, params := usingAuthServiceQuery(authService, authData)
+		params["Offset"] = offset
+		params["Limit"] = limit
+
+		var users []*model.User
+		if _, err := us.GetReplica().Select(&users, "SELECT * "+query+" ORDER BY CreateAt ASC, Id ASC LIMIT :Limit OFFSET :Offset", params); err != nil {
+			result.Err = model.NewAppError("SqlUserStore.GetUsingAuthService", "store.sql_user.get_by_auth.other.app_error", nil, "authService="+authService+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = users
+	})
+}
+
+func (us SqlUserStore) CountUsingAuthService(authService string, authData string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		query, params := usingAuthServiceQuery(authService, authData)
+
+		count, err := us.GetReplica().SelectInt("SELECT COUNT(Id) "+query, params)
+		if err != nil {
+			result.Err = model.NewAppError("SqlUserStore.CountUsingAuthService", "store.sql_user.get_by_auth.other.app_error", nil, "authService="+authService+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = count
+	})
+}
+
 func (us SqlUserStore) GetByUsername(username string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		user := model.User{}
@@ -861,7 +1076,7 @@ func (us SqlUserStore) VerifyEmail(userId string) store.StoreChannel {
 
 func (us SqlUserStore) GetTotalUsersCount() store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
-		if count, err := us.GetReplica().SelectInt("SELECT COUNT(Id) FROM Users"); err != nil {
+		if count, err := us.GetReplica().SelectInt("SELECT COUNT(Id) FROM Users WHERE IsBot = false"); err != nil {
 			result.Err = model.NewAppError("SqlUserStore.GetTotalUsersCount", "store.sql_user.get_total_users_count.app_error", nil, err.Error(), http.StatusInternalServerError)
 		} else {
 			result.Data = count
@@ -869,10 +1084,97 @@ func (us SqlUserStore) GetTotalUsersCount() store.StoreChannel {
 	})
 }
 
+// userFilterQuery returns the FROM and WHERE clauses that select the users matching the filter.
+func userFilterQuery(filter *model.UserFilter) (string, map[string]interface{}) {
+	from := "Users"
+	where := []string{"1 = 1"}
+	params := map[string]interface{}{}
+
+	if filter.TeamId != "" {
+		from = "Users INNER JOIN TeamMembers ON TeamMembers.UserId = Users.Id AND TeamMembers.DeleteAt = 0"
+		where = append(where, "TeamMembers.TeamId = :TeamId")
+		params["TeamId"] = filter.TeamId
+	}
+
+	if filter.Role != "" {
+		where = append(where, "Users.Roles LIKE :Roles")
+		params["Roles"] = "%" + filter.Role + "%"
+	}
+
+	if filter.AuthService == model.USER_AUTH_SERVICE_EMAIL {
+		where = append(where, "Users.AuthService = ''")
+	} else if filter.AuthService != "" {
+		where = append(where, "Users.AuthService = :AuthService")
+		params["AuthService"] = filter.AuthService
+	}
+
+	if filter.Active != nil && *filter.Active {
+		where = append(where, "Users.DeleteAt = 0")
+	} else if filter.Active != nil {
+		where = append(where, "Users.DeleteAt > 0")
+	}
+
+	if filter.CreatedAfter > 0 {
+		where = append(where, "Users.CreateAt >= :CreatedAfter")
+		params["CreatedAfter"] = filter.CreatedAfter
+	}
+	if filter.CreatedBefore > 0 {
+		where = append(where, "Users.CreateAt < :CreatedBefore")
+		params["CreatedBefore"] = filter.CreatedBefore
+	}
+
+	if filter.LastSeenAfter > 0 {
+		where = append(where, "Users.LastSeenAt >= :LastSeenAfter")
+		params["LastSeenAfter"] = filter.LastSeenAfter
+	}
+	if filter.LastSeenBefore > 0 {
+		where = append(where, "Users.LastSeenAt < :LastSeenBefore")
+		params["LastSeenBefore"] = filter.LastSeenBefore
+	}
+
+	return "FROM " + from + " WHERE " + strings.Join(where, " AND "), params
+}
+
+func (us SqlUserStore) GetProfilesWithFilter(filter *model.UserFilter, offset int, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		query, params := userFilterQuery(filter)
+		params["Offset"] = offset
+		params["Limit"] = limit
+
+		var users []*model.User
+		if _, err := us.GetReplica().Select(&users, "SELECT Users.* "+query+" ORDER BY Users.Username ASC LIMIT :Limit OFFSET :Offset", params); err != nil {
+			result.Err = model.NewAppError("SqlUserStore.GetProfilesWithFilter", "store.sql_user.get_profiles.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			for _, u := range users {
+				u.Sanitize(map[string]bool{})
+			}
+
+			result.Data = users
+		}
+	})
+}
+
+func (us SqlUserStore) CountWithFilter(filter *model.UserFilter) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		query, params := userFilterQuery(filter)
+
+		if count, err := us.GetReplica().SelectInt("SELECT COUNT(Users.Id) "+query, params); err != nil {
+			result.Err = model.NewAppError("SqlUserStore.CountWithFilter", "store.sql_user.count_with_filter.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = count
+		}
+	})
+}
+
 func (us SqlUserStore) PermanentDelete(userId string) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		if _, err := us.GetMaster().Exec("DELETE FROM Users WHERE Id = :UserId", map[string]interface{}{"UserId": userId}); err != nil {
 			result.Err = model.NewAppError("SqlUserStore.PermanentDelete", "store.sql_user.permanent_delete.app_error", nil, "userId="+userId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := us.GetMaster().Exec("DELETE FROM UsernameHistory WHERE UserId = :UserId", map[string]interface{}{"UserId": userId}); err != nil {
+			result.Err = model.NewAppError("SqlUserStore.PermanentDelete", "store.sql_user.permanent_delete.app_error", nil, "userId="+userId+", "+err.Error(), http.StatusInternalServerError)
 		}
 	})
 }
@@ -881,9 +1183,9 @@ func (us SqlUserStore) AnalyticsUniqueUserCount(teamId string) store.StoreChanne
 	return store.Do(func(result *store.StoreResult) {
 		query := ""
 		if len(teamId) > 0 {
-			query = "SELECT COUNT(DISTINCT Users.Email) From Users, TeamMembers WHERE TeamMembers.TeamId = :TeamId AND Users.Id = TeamMembers.UserId AND TeamMembers.DeleteAt = 0 AND Users.DeleteAt = 0"
+			query = "SELECT COUNT(DISTINCT Users.Email) From Users, TeamMembers WHERE TeamMembers.TeamId = :TeamId AND Users.Id = TeamMembers.UserId AND TeamMembers.DeleteAt = 0 AND Users.DeleteAt = 0 AND Users.IsBot = false"
 		} else {
-			query = "SELECT COUNT(DISTINCT Email) FROM Users WHERE DeleteAt = 0"
+			query = "SELECT COUNT(DISTINCT Email) FROM Users WHERE DeleteAt = 0 AND IsBot = false"
 		}
 
 		v, err := us.GetReplica().SelectInt(query, map[string]interface{}{"TeamId": teamId})
@@ -942,7 +1244,7 @@ func (us SqlUserStore) Search(teamId string, term string, options map[string]boo
 
 		if teamId == "" {
 
-			// Id != '' is added because both SEARCH_CLAUSE and INACTIVE_CLAUSE start with an AND
+			// Id != '' is added because SEARCH_CLAUSE, INACTIVE_CLAUSE and BOTS_CLAUSE all start with an AND
 			searchQuery = `
 			SELECT
 				*
@@ -952,7 +1254,8 @@ func (us SqlUserStore) Search(teamId string, term string, options map[string]boo
 				Id != ''
 				SEARCH_CLAUSE
 				INACTIVE_CLAUSE
-				ORDER BY Username ASC
+				BOTS_CLAUSE
+				ORDER_CLAUSE
 			LIMIT 100`
 		} else {
 			searchQuery = `
@@ -966,7 +1269,8 @@ func (us SqlUserStore) Search(teamId string, term string, options map[string]boo
 				AND TeamMembers.DeleteAt = 0
 				SEARCH_CLAUSE
 				INACTIVE_CLAUSE
-				ORDER BY Users.Username ASC
+				BOTS_CLAUSE
+				ORDER_CLAUSE
 			LIMIT 100`
 		}
 
@@ -992,7 +1296,8 @@ func (us SqlUserStore) SearchWithoutTeam(term string, options map[string]bool) s
 				AND TeamMembers.DeleteAt = 0) = 0
 			SEARCH_CLAUSE
 			INACTIVE_CLAUSE
-			ORDER BY Username ASC
+			BOTS_CLAUSE
+			ORDER_CLAUSE
 		LIMIT 100`
 
 		*result = us.performSearch(searchQuery, term, options, map[string]interface{}{})
@@ -1013,7 +1318,8 @@ func (us SqlUserStore) SearchNotInTeam(notInTeamId string, term string, options
 				(tm.UserId IS NULL OR tm.DeleteAt != 0)
 				SEARCH_CLAUSE
 				INACTIVE_CLAUSE
-			ORDER BY Users.Username ASC
+				BOTS_CLAUSE
+			ORDER_CLAUSE
 			LIMIT 100`
 
 		*result = us.performSearch(searchQuery, term, options, map[string]interface{}{"NotInTeamId": notInTeamId})
@@ -1036,7 +1342,8 @@ func (us SqlUserStore) SearchNotInChannel(teamId string, channelId string, term
 				cm.UserId IS NULL
 				SEARCH_CLAUSE
 				INACTIVE_CLAUSE
-			ORDER BY Users.Username ASC
+				BOTS_CLAUSE
+			ORDER_CLAUSE
 			LIMIT 100`
 		} else {
 			searchQuery = `
@@ -1054,7 +1361,8 @@ func (us SqlUserStore) SearchNotInChannel(teamId string, channelId string, term
 				cm.UserId IS NULL
 				SEARCH_CLAUSE
 				INACTIVE_CLAUSE
-			ORDER BY Users.Username ASC
+				BOTS_CLAUSE
+			ORDER_CLAUSE
 			LIMIT 100`
 		}
 
@@ -1075,7 +1383,8 @@ func (us SqlUserStore) SearchInChannel(channelId string, term string, options ma
             AND ChannelMembers.UserId = Users.Id
             SEARCH_CLAUSE
             INACTIVE_CLAUSE
-            ORDER BY Users.Username ASC
+            BOTS_CLAUSE
+            ORDER_CLAUSE
         LIMIT 100`
 
 		*result = us.performSearch(searchQuery, term, options, map[string]interface{}{"ChannelId": channelId})
@@ -1107,13 +1416,15 @@ var spaceFulltextSearchChar = []string{
 	"@",
 }
 
+// generateSearchQuery matches each term against the start of any of the fields. The comparisons are written so that
+// they can use the prefix indexes created in CreateIndexesIfNotExists, so the terms must already be lowercase.
 func generateSearchQuery(searchQuery string, terms []string, fields []string, parameters map[string]interface{}, isPostgreSQL bool) string {
 	searchTerms := []string{}
 	for i, term := range terms {
 		searchFields := []string{}
 		for _, field := range fields {
 			if isPostgreSQL {
-				searchFields = append(searchFields, fmt.Sprintf("lower(%s) LIKE lower(%s) escape '*' ", field, fmt.Sprintf(":Term%d", i)))
+				searchFields = append(searchFields, fmt.Sprintf("lower(%s) LIKE %s escape '*' ", field, fmt.Sprintf(":Term%d", i)))
 			} else {
 				searchFields = append(searchFields, fmt.Sprintf("%s LIKE %s escape '*' ", field, fmt.Sprintf(":Term%d", i)))
 			}
@@ -1126,8 +1437,22 @@ func generateSearchQuery(searchQuery string, terms []string, fields []string, pa
 	return strings.Replace(searchQuery, "SEARCH_CLAUSE", fmt.Sprintf(" AND %s ", searchClause), 1)
 }
 
-func (us SqlUserStore) performSearch(searchQuery string, term string, options map[string]bool, parameters map[string]interface{}) store.StoreResult {
-	result := store.StoreResult{}
+// generateSearchOrder puts the user whose username is exactly the term first, followed by everyone else in order of
+// username. Usernames are unique, so the same users are always returned when the results are limited.
+func generateSearchOrder(searchQuery string, term string, parameters map[string]interface{}) string {
+	orderClause := "ORDER BY Users.Username ASC"
+
+	if term = strings.TrimSpace(term); term != "" && !strings.ContainsAny(term, " \t") {
+		// Usernames are always saved in lowercase
+		orderClause = "ORDER BY CASE WHEN Users.Username = :ExactTerm THEN 0 ELSE 1 END, Users.Username ASC"
+		parameters["ExactTerm"] = strings.ToLower(term)
+	}
+
+	return strings.Replace(searchQuery, "ORDER_CLAUSE", orderClause, 1)
+}
+
+func (us SqlUserStore) generateUserSearchQuery(searchQuery string, term string, options map[string]bool, parameters map[string]interface{}) (string, []string) {
+	searchQuery = generateSearchOrder(searchQuery, term, parameters)
 
 	// These chars must be removed from the like query.
 	for _, c := range ignoreLikeSearchChar {
@@ -1154,13 +1479,27 @@ func (us SqlUserStore) performSearch(searchQuery string, term string, options ma
 		searchQuery = strings.Replace(searchQuery, "INACTIVE_CLAUSE", "AND Users.DeleteAt = 0", 1)
 	}
 
+	if ok := options[store.USER_SEARCH_OPTION_EXCLUDE_BOTS]; ok {
+		searchQuery = strings.Replace(searchQuery, "BOTS_CLAUSE", "AND Users.IsBot = false", 1)
+	} else {
+		searchQuery = strings.Replace(searchQuery, "BOTS_CLAUSE", "", 1)
+	}
+
 	if strings.TrimSpace(term) == "" {
 		searchQuery = strings.Replace(searchQuery, "SEARCH_CLAUSE", "", 1)
 	} else {
 		isPostgreSQL := us.DriverName() == model.DATABASE_DRIVER_POSTGRES
-		searchQuery = generateSearchQuery(searchQuery, strings.Fields(term), searchType, parameters, isPostgreSQL)
+		searchQuery = generateSearchQuery(searchQuery, strings.Fields(strings.ToLower(term)), searchType, parameters, isPostgreSQL)
 	}
 
+	return searchQuery, searchType
+}
+
+func (us SqlUserStore) performSearch(searchQuery string, term string, options map[string]bool, parameters map[string]interface{}) store.StoreResult {
+	result := store.StoreResult{}
+
+	searchQuery, searchType := us.generateUserSearchQuery(searchQuery, term, options, parameters)
+
 	var users []*model.User
 
 	if _, err := us.GetReplica().Select(&users, searchQuery, parameters); err != nil {
@@ -1179,7 +1518,7 @@ func (us SqlUserStore) performSearch(searchQuery string, term string, options ma
 
 func (us SqlUserStore) AnalyticsGetInactiveUsersCount() store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
-		if count, err := us.GetReplica().SelectInt("SELECT COUNT(Id) FROM Users WHERE DeleteAt > 0"); err != nil {
+		if count, err := us.GetReplica().SelectInt("SELECT COUNT(Id) FROM Users WHERE DeleteAt > 0 AND IsBot = false"); err != nil {
 			result.Err = model.NewAppError("SqlUserStore.AnalyticsGetInactiveUsersCount", "store.sql_user.analytics_get_inactive_users_count.app_error", nil, err.Error(), http.StatusInternalServerError)
 		} else {
 			result.Data = count
@@ -1187,6 +1526,16 @@ func (us SqlUserStore) AnalyticsGetInactiveUsersCount() store.StoreChannel {
 	})
 }
 
+func (us SqlUserStore) AnalyticsBotCount() store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if count, err := us.GetReplica().SelectInt("SELECT COUNT(Id) FROM Users WHERE DeleteAt = 0 AND IsBot = true"); err != nil {
+			result.Err = model.NewAppError("SqlUserStore.AnalyticsBotCount", "store.sql_user.analytics_bot_count.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+			result.Data = count
+		}
+	})
+}
+
 func (us SqlUserStore) AnalyticsGetSystemAdminCount() store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		if count, err := us.GetReplica().SelectInt("SELECT count(*) FROM Users WHERE Roles LIKE :Roles and DeleteAt = 0", map[string]interface{}{"Roles": "%system_admin%"}); err != nil {
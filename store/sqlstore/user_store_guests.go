@@ -0,0 +1,189 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+// usersInUserChannelsQuery selects the ids of every user sharing a channel with :UserId. TEAM_CLAUSE
+// is replaced to optionally limit the channels to a single team.
+const usersInUserChannelsQuery = `
+	SELECT
+		OtherMembers.UserId
+	FROM
+		ChannelMembers UserMembers,
+		ChannelMembers OtherMembers,
+		Channels
+	WHERE
+		UserMembers.UserId = :UserId
+		AND OtherMembers.ChannelId = UserMembers.ChannelId
+		AND Channels.Id = UserMembers.ChannelId
+		AND Channels.DeleteAt = 0
+		TEAM_CLAUSE`
+
+func buildUsersInUserChannelsQuery(teamId string) string {
+	if teamId == "" {
+		return strings.Replace(usersInUserChannelsQuery, "TEAM_CLAUSE", "", 1)
+	}
+
+	return strings.Replace(usersInUserChannelsQuery, "TEAM_CLAUSE", "AND Channels.TeamId = :TeamId", 1)
+}
+
+func (us SqlUserStore) GetProfilesInUserChannels(userId string, teamId string, offset int, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var users []*model.User
+
+		query := `
+			SELECT
+				Users.*
+			FROM
+				Users
+			WHERE
+				Users.Id IN (` + buildUsersInUserChannelsQuery(teamId) + `)
+			ORDER BY
+				Users.Username ASC
+			LIMIT :Limit OFFSET :Offset`
+
+		if _, err := us.GetReplica().Select(&users, query, map[string]interface{}{"UserId": userId, "TeamId": teamId, "Offset": offset, "Limit": limit}); err != nil {
+			result.Err = model.NewAppError("SqlUserStore.GetProfilesInUserChannels", "store.sql_user.get_profiles.app_error", nil, err.Error(), http.StatusInternalServerError)
+		} else {
+
+			for _, u := range users {
+				u.Sanitize(map[string]bool{})
+			}
+
+			result.Data = users
+		}
+	})
+}
+
+func (us SqlUserStore) SearchInUserChannels(userId string, teamId string, term string, options map[string]bool) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		searchQuery := `
+			SELECT
+				Users.*
+			FROM
+				Users
+			WHERE
+				Users.Id IN (` + buildUsersInUserChannelsQuery(teamId) + `)
+				SEARCH_CLAUSE
+				INACTIVE_CLAUSE
+				BOTS_CLAUSE
+				ORDER_CLAUSE
+			LIMIT 100`
+
+		*result = us.performSearch(searchQuery, term, options, map[string]interface{}{"UserId": userId, "TeamId": teamId})
+	})
+}
+
+func (us SqlUserStore) GetIdsSharingChannelsWithUser(userId string, userIds []string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		ids := []string{}
+		if len(userIds) == 0 {
+			result.Data = ids
+			return
+		}
+
+		props := map[string]interface{}{"UserId": userId}
+		idQuery := ""
+
+		for index, id := range userIds {
+			if len(idQuery) > 0 {
+				idQuery += ", "
+			}
+
+			props["userId"+strconv.Itoa(index)] = id
+			idQuery += ":userId" + strconv.Itoa(index)
+		}
+
+		query := `
+			SELECT DISTINCT
+				OtherMembers.UserId
+			FROM
+				ChannelMembers UserMembers,
+				ChannelMembers OtherMembers
+			WHERE
+				UserMembers.UserId = :UserId
+				AND OtherMembers.ChannelId = UserMembers.ChannelId
+				AND OtherMembers.UserId IN (` + idQuery + `)`
+
+		if _, err := us.GetReplica().Select(&ids, query, props); err != nil {
+			result.Err = model.NewAppError("SqlUserStore.GetIdsSharingChannelsWithUser", "store.sql_user.get_ids_sharing_channels.app_error", nil, "user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = ids
+	})
+}
+
+func (us SqlUserStore) PromoteGuestToUser(userId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		result.Err = us.updateGuestRoles("SqlUserStore.PromoteGuestToUser", userId,
+			"REPLACE(Roles, '"+model.SYSTEM_GUEST_ROLE_ID+"', '"+model.SYSTEM_USER_ROLE_ID+"')",
+			"REPLACE(Roles, '"+model.TEAM_GUEST_ROLE_ID+"', '"+model.TEAM_USER_ROLE_ID+"')",
+			"REPLACE(Roles, '"+model.CHANNEL_GUEST_ROLE_ID+"', '"+model.CHANNEL_USER_ROLE_ID+"')",
+		)
+	})
+}
+
+// DemoteUserToGuest replaces all of a user's system, team and channel roles with the matching guest
+// roles. Admin roles aren't kept, since guests can't hold them.
+func (us SqlUserStore) DemoteUserToGuest(userId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		result.Err = us.updateGuestRoles("SqlUserStore.DemoteUserToGuest", userId,
+			"'"+model.SYSTEM_GUEST_ROLE_ID+"'",
+			"'"+model.TEAM_GUEST_ROLE_ID+"'",
+			"'"+model.CHANNEL_GUEST_ROLE_ID+"'",
+		)
+	})
+}
+
+// updateGuestRoles sets the roles of a user and all of their team and channel memberships to the
+// given SQL expressions in a single transaction.
+func (us SqlUserStore) updateGuestRoles(where string, userId string, userRoles string, teamRoles string, channelRoles string) *model.AppError {
+	transaction, err := us.GetMaster().Begin()
+	if err != nil {
+		return model.NewAppError(where, "store.sql_user.update_guest_roles.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	params := map[string]interface{}{"UserId": userId, "UpdateAt": model.GetMillis()}
+
+	// The user is taken out of their channels' guest counts before their roles change and added back afterwards
+	if err := updateMemberCountsT(transaction, userId, "", -1); err != nil {
+		transaction.Rollback()
+		return model.NewAppError(where, "store.sql_user.update_guest_roles.member_counts.app_error", nil, "user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+	}
+
+	if _, err := transaction.Exec("UPDATE Users SET Roles = "+userRoles+", UpdateAt = :UpdateAt WHERE Id = :UserId", params); err != nil {
+		transaction.Rollback()
+		return model.NewAppError(where, "store.sql_user.update_guest_roles.users.app_error", nil, "user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+	}
+
+	if err := updateMemberCountsT(transaction, userId, "", 1); err != nil {
+		transaction.Rollback()
+		return model.NewAppError(where, "store.sql_user.update_guest_roles.member_counts.app_error", nil, "user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+	}
+
+	if _, err := transaction.Exec("UPDATE TeamMembers SET Roles = "+teamRoles+" WHERE UserId = :UserId", params); err != nil {
+		transaction.Rollback()
+		return model.NewAppError(where, "store.sql_user.update_guest_roles.team_members.app_error", nil, "user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+	}
+
+	if _, err := transaction.Exec("UPDATE ChannelMembers SET Roles = "+channelRoles+" WHERE UserId = :UserId", params); err != nil {
+		transaction.Rollback()
+		return model.NewAppError(where, "store.sql_user.update_guest_roles.channel_members.app_error", nil, "user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+	}
+
+	if err := transaction.Commit(); err != nil {
+		return model.NewAppError(where, "store.sql_user.update_guest_roles.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+const userSearchTestQuery = `
+	SELECT
+		*
+	FROM
+		Users
+	WHERE
+		Id != ''
+		SEARCH_CLAUSE
+		INACTIVE_CLAUSE
+		BOTS_CLAUSE
+		ORDER_CLAUSE
+	LIMIT 100`
+
+func TestUserStoreSearchUsesIndexes(t *testing.T) {
+	for _, st := range storeTypes {
+		st := st
+		t.Run(st.Name, func(t *testing.T) {
+			// MySQL's case insensitive collation lets it use the plain indexes, but its planner can't be made to
+			// prefer them on a table this small
+			if *st.Settings.DriverName != model.DATABASE_DRIVER_POSTGRES {
+				t.Skip("only checked on PostgreSQL")
+			}
+
+			supplier := NewSqlSupplier(*st.Settings, nil, nil)
+			defer supplier.Close()
+
+			us := NewSqlUserStore(supplier, nil).(*SqlUserStore)
+
+			parameters := map[string]interface{}{}
+			query, _ := us.generateUserSearchQuery(userSearchTestQuery, "Jim", map[string]bool{
+				store.USER_SEARCH_OPTION_NAMES_ONLY_NO_FULL_NAME: true,
+				store.USER_SEARCH_OPTION_ALLOW_INACTIVE:          true,
+			}, parameters)
+
+			tx, err := supplier.GetMaster().Begin()
+			require.Nil(t, err)
+			defer tx.Rollback()
+
+			// Otherwise a sequential scan is cheaper than any index while the table is nearly empty
+			_, err = tx.Exec("SET LOCAL enable_seqscan = off")
+			require.Nil(t, err)
+
+			var plan []string
+			_, err = tx.Select(&plan, "EXPLAIN "+query, parameters)
+			require.Nil(t, err)
+
+			joined := strings.Join(plan, "\n")
+			assert.Contains(t, joined, "idx_users_username_lower_textpattern")
+			assert.Contains(t, joined, "idx_users_nickname_lower_textpattern")
+			assert.NotContains(t, joined, "Seq Scan")
+		})
+	}
+}
+
+// BenchmarkUserStoreSearch searches a large number of users the way that autocomplete does. The users are only
+// created the first time that it's run against a database.
+func BenchmarkUserStoreSearch(b *testing.B) {
+	const userCount = 500000
+	const batchSize = 1000
+
+	for _, st := range storeTypes {
+		st := st
+		b.Run(st.Name, func(b *testing.B) {
+			supplier := NewSqlSupplier(*st.Settings, nil, nil)
+			defer supplier.Close()
+
+			count, err := supplier.GetMaster().SelectInt("SELECT COUNT(*) FROM Users WHERE Username LIKE 'bench%'")
+			require.Nil(b, err)
+
+			for created := int(count); created < userCount; created += batchSize {
+				tx, err := supplier.GetMaster().Begin()
+				require.Nil(b, err)
+
+				users := make([]interface{}, 0, batchSize)
+				for i := created; i < created+batchSize; i++ {
+					user := &model.User{
+						Email:     model.NewId() + "@example.com",
+						Username:  fmt.Sprintf("bench%07d", i),
+						Nickname:  fmt.Sprintf("nick%07d", i),
+						FirstName: fmt.Sprintf("first%07d", i),
+						LastName:  fmt.Sprintf("last%07d", i),
+						IsBot:     i%50 == 0,
+					}
+					if i%20 == 0 {
+						user.DeleteAt = model.GetMillis()
+					}
+					user.PreSave()
+
+					users = append(users, user)
+				}
+
+				require.Nil(b, tx.Insert(users...))
+				require.Nil(b, tx.Commit())
+			}
+
+			searchOptions := map[string]bool{
+				store.USER_SEARCH_OPTION_NAMES_ONLY:   true,
+				store.USER_SEARCH_OPTION_EXCLUDE_BOTS: true,
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				result := <-supplier.User().Search("", fmt.Sprintf("bench%04d", i%10000), searchOptions)
+				require.Nil(b, result.Err)
+			}
+		})
+	}
+}
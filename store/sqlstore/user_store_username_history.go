@@ -0,0 +1,88 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+// SaveUsernameHistory records one of a user's old usernames, taking it over from any other user that used to have
+// it. Only the newest maxPerUser usernames are kept for each user.
+func (us SqlUserStore) SaveUsernameHistory(history *model.UsernameHistory, maxPerUser int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		history.PreSave()
+		if result.Err = history.IsValid(); result.Err != nil {
+			return
+		}
+
+		transaction, err := us.GetMaster().Begin()
+		if err != nil {
+			result.Err = model.NewAppError("SqlUserStore.SaveUsernameHistory", "store.sql_user.username_history.open_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := transaction.Exec("DELETE FROM UsernameHistory WHERE Username = :Username", map[string]interface{}{"Username": history.Username}); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlUserStore.SaveUsernameHistory", "store.sql_user.username_history.save.app_error", nil, "user_id="+history.UserId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := transaction.Insert(history); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlUserStore.SaveUsernameHistory", "store.sql_user.username_history.save.app_error", nil, "user_id="+history.UserId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var usernames []string
+		if _, err := transaction.Select(&usernames, "SELECT Username FROM UsernameHistory WHERE UserId = :UserId ORDER BY CreateAt DESC, Username", map[string]interface{}{"UserId": history.UserId}); err != nil {
+			transaction.Rollback()
+			result.Err = model.NewAppError("SqlUserStore.SaveUsernameHistory", "store.sql_user.username_history.save.app_error", nil, "user_id="+history.UserId+", "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		for i := maxPerUser; i < len(usernames); i++ {
+			if _, err := transaction.Exec("DELETE FROM UsernameHistory WHERE UserId = :UserId AND Username = :Username", map[string]interface{}{"UserId": history.UserId, "Username": usernames[i]}); err != nil {
+				transaction.Rollback()
+				result.Err = model.NewAppError("SqlUserStore.SaveUsernameHistory", "store.sql_user.username_history.save.app_error", nil, "user_id="+history.UserId+", "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := transaction.Commit(); err != nil {
+			result.Err = model.NewAppError("SqlUserStore.SaveUsernameHistory", "store.sql_user.username_history.commit_transaction.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = history
+	})
+}
+
+func (us SqlUserStore) GetUsernameHistoryByUsername(username string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var history model.UsernameHistory
+		if err := us.GetReplica().SelectOne(&history, "SELECT * FROM UsernameHistory WHERE Username = :Username", map[string]interface{}{"Username": username}); err != nil {
+			if err == sql.ErrNoRows {
+				result.Err = model.NewAppError("SqlUserStore.GetUsernameHistoryByUsername", "store.sql_user.username_history.get_by_username.missing.app_error", nil, "username="+username, http.StatusNotFound)
+			} else {
+				result.Err = model.NewAppError("SqlUserStore.GetUsernameHistoryByUsername", "store.sql_user.username_history.get_by_username.app_error", nil, "username="+username+", "+err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		result.Data = &history
+	})
+}
+
+// DeleteUsernameHistory forgets all of a user's old usernames.
+func (us SqlUserStore) DeleteUsernameHistory(userId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if _, err := us.GetMaster().Exec("DELETE FROM UsernameHistory WHERE UserId = :UserId", map[string]interface{}{"UserId": userId}); err != nil {
+			result.Err = model.NewAppError("SqlUserStore.DeleteUsernameHistory", "store.sql_user.username_history.delete.app_error", nil, "user_id="+userId+", "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
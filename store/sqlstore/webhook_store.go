@@ -48,6 +48,7 @@ func NewSqlWebhookStore(sqlStore SqlStore, metrics einterfaces.MetricsInterface)
 		table.ColMap("TeamId").SetMaxSize(26)
 		table.ColMap("DisplayName").SetMaxSize(64)
 		table.ColMap("Description").SetMaxSize(128)
+		table.ColMap("AllowedChannelIds").SetMaxSize(1024)
 
 		tableo := db.AddTableWithName(model.OutgoingWebhook{}, "OutgoingWebhooks").SetKeys(false, "Id")
 		tableo.ColMap("Id").SetMaxSize(26)
@@ -61,6 +62,8 @@ func NewSqlWebhookStore(sqlStore SqlStore, metrics einterfaces.MetricsInterface)
 		tableo.ColMap("Description").SetMaxSize(128)
 		tableo.ColMap("ContentType").SetMaxSize(128)
 		tableo.ColMap("TriggerWhen").SetMaxSize(1)
+		tableo.ColMap("SigningSecret").SetMaxSize(64)
+		tableo.ColMap("SigningAlgorithm").SetMaxSize(16)
 	}
 
 	return s
@@ -186,6 +189,17 @@ func (s SqlWebhookStore) PermanentDeleteIncomingByChannel(channelId string) stor
 	})
 }
 
+func (s SqlWebhookStore) PermanentDeleteIncomingByTeam(teamId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		_, err := s.GetMaster().Exec("DELETE FROM IncomingWebhooks WHERE TeamId = :TeamId", map[string]interface{}{"TeamId": teamId})
+		if err != nil {
+			result.Err = model.NewAppError("SqlWebhookStore.PermanentDeleteIncomingByTeam", "store.sql_webhooks.permanent_delete_incoming_by_team.app_error", nil, "id="+teamId+", err="+err.Error(), http.StatusInternalServerError)
+		}
+
+		s.ClearCaches()
+	})
+}
+
 func (s SqlWebhookStore) GetIncomingList(offset, limit int) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		var webhooks []*model.IncomingWebhook
@@ -333,6 +347,17 @@ func (s SqlWebhookStore) PermanentDeleteOutgoingByChannel(channelId string) stor
 	})
 }
 
+func (s SqlWebhookStore) PermanentDeleteOutgoingByTeam(teamId string) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		_, err := s.GetMaster().Exec("DELETE FROM OutgoingWebhooks WHERE TeamId = :TeamId", map[string]interface{}{"TeamId": teamId})
+		if err != nil {
+			result.Err = model.NewAppError("SqlWebhookStore.PermanentDeleteOutgoingByTeam", "store.sql_webhooks.permanent_delete_outgoing_by_team.app_error", nil, "id="+teamId+", err="+err.Error(), http.StatusInternalServerError)
+		}
+
+		s.ClearCaches()
+	})
+}
+
 func (s SqlWebhookStore) UpdateOutgoing(hook *model.OutgoingWebhook) store.StoreChannel {
 	return store.Do(func(result *store.StoreResult) {
 		hook.UpdateAt = model.GetMillis()
@@ -0,0 +1,102 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package sqlstore
+
+import (
+	"net/http"
+
+	"github.com/mattermost/gorp"
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+type SqlWebSocketOutboxStore struct {
+	SqlStore
+}
+
+func NewSqlWebSocketOutboxStore(sqlStore SqlStore) store.WebSocketOutboxStore {
+	s := &SqlWebSocketOutboxStore{sqlStore}
+
+	for _, db := range sqlStore.GetAllConns() {
+		table := db.AddTableWithName(model.WebSocketOutboxEvent{}, "WebSocketOutbox").SetKeys(false, "Id")
+		table.ColMap("Id").SetMaxSize(26)
+		table.ColMap("Event").SetMaxSize(model.WEBSOCKET_OUTBOX_EVENT_MAX_LENGTH)
+		table.ColMap("PostId").SetMaxSize(26)
+		table.ColMap("Data").SetMaxSize(model.WEBSOCKET_OUTBOX_EVENT_DATA_MAX_LENGTH)
+	}
+
+	return s
+}
+
+func (s SqlWebSocketOutboxStore) CreateIndexesIfNotExists() {
+	s.CreateIndexIfNotExists("idx_websocket_outbox_create_at", "WebSocketOutbox", "CreateAt")
+}
+
+// saveWebSocketOutboxEvent saves the event as part of the transaction that makes the change that it's about.
+func saveWebSocketOutboxEvent(transaction *gorp.Transaction, event *model.WebSocketOutboxEvent) error {
+	event.PreSave()
+	if err := event.IsValid(); err != nil {
+		return err
+	}
+
+	return transaction.Insert(event)
+}
+
+// GetUnsent returns the events created from after up to before that haven't been sent, oldest first.
+func (s SqlWebSocketOutboxStore) GetUnsent(after int64, before int64, limit int) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var events []*model.WebSocketOutboxEvent
+
+		if _, err := s.GetMaster().Select(&events,
+			`SELECT
+				*
+			FROM
+				WebSocketOutbox
+			WHERE
+				SentAt = 0
+				AND CreateAt >= :After
+				AND CreateAt < :Before
+			ORDER BY
+				CreateAt
+			LIMIT :Limit`, map[string]interface{}{"After": after, "Before": before, "Limit": limit}); err != nil {
+			result.Err = model.NewAppError("SqlWebSocketOutboxStore.GetUnsent", "store.sql_websocket_outbox.get_unsent.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = events
+	})
+}
+
+func (s SqlWebSocketOutboxStore) MarkSent(id string, sentAt int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		if _, err := s.GetMaster().Exec("UPDATE WebSocketOutbox SET SentAt = :SentAt WHERE Id = :Id AND SentAt = 0", map[string]interface{}{"Id": id, "SentAt": sentAt}); err != nil {
+			result.Err = model.NewAppError("SqlWebSocketOutboxStore.MarkSent", "store.sql_websocket_outbox.mark_sent.app_error", nil, "id="+id+", "+err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+func (s SqlWebSocketOutboxStore) PermanentDeleteBatch(endTime int64, limit int64) store.StoreChannel {
+	return store.Do(func(result *store.StoreResult) {
+		var query string
+		if s.DriverName() == "postgres" {
+			query = "DELETE from WebSocketOutbox WHERE Id = any (array (SELECT Id FROM WebSocketOutbox WHERE CreateAt < :EndTime LIMIT :Limit))"
+		} else {
+			query = "DELETE from WebSocketOutbox WHERE CreateAt < :EndTime LIMIT :Limit"
+		}
+
+		sqlResult, err := s.GetMaster().Exec(query, map[string]interface{}{"EndTime": endTime, "Limit": limit})
+		if err != nil {
+			result.Err = model.NewAppError("SqlWebSocketOutboxStore.PermanentDeleteBatch", "store.sql_websocket_outbox.permanent_delete_batch.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rowsAffected, err := sqlResult.RowsAffected()
+		if err != nil {
+			result.Err = model.NewAppError("SqlWebSocketOutboxStore.PermanentDeleteBatch", "store.sql_websocket_outbox.permanent_delete_batch.app_error", nil, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		result.Data = rowsAffected
+	})
+}
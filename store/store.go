@@ -4,9 +4,13 @@
 package store
 
 import (
+	"errors"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils/tracing"
 )
 
 type StoreResult struct {
@@ -16,17 +20,47 @@ type StoreResult struct {
 
 type StoreChannel chan StoreResult
 
+// ErrReadOnly is returned by the database connections for any query that would make changes while the store is
+// read-only.
+var ErrReadOnly = errors.New("writes are disabled while the database is read-only")
+
+// NewReadOnlyError returns the error that's returned by writes while the store is read-only.
+func NewReadOnlyError(where string) *model.AppError {
+	return model.NewAppError(where, READ_ONLY_ERROR, nil, "", http.StatusServiceUnavailable)
+}
+
+// replaceReadOnlyError replaces the error from a write that failed because the store is read-only, which only
+// mentions ErrReadOnly in its details, with one that callers can recognize.
+func replaceReadOnlyError(result *StoreResult) {
+	if result.Err != nil && result.Err.Id != READ_ONLY_ERROR && strings.Contains(result.Err.DetailedError, ErrReadOnly.Error()) {
+		result.Err = NewReadOnlyError(result.Err.Where)
+	}
+}
+
 func Do(f func(result *StoreResult)) StoreChannel {
 	storeChannel := make(StoreChannel, 1)
+	endSpan := startSpan()
 	go func() {
 		result := StoreResult{}
 		f(&result)
+		replaceReadOnlyError(&result)
+		endSpan()
 		storeChannel <- result
 		close(storeChannel)
 	}()
 	return storeChannel
 }
 
+// startSpan begins timing a store call for the trace of the request making it, if there is one, naming it after the
+// store method that called Do or RunQuery.
+func startSpan() func() {
+	if !tracing.Active() {
+		return func() {}
+	}
+
+	return tracing.StartSpan(model.REQUEST_TRACE_SPAN_STORE, tracing.CallerName(2))
+}
+
 func Must(sc StoreChannel) interface{} {
 	r := <-sc
 	if r.Err != nil {
@@ -63,6 +97,23 @@ type Store interface {
 	Job() JobStore
 	UserAccessToken() UserAccessTokenStore
 	ChannelMemberHistory() ChannelMemberHistoryStore
+	Thread() ThreadStore
+	CustomGroup() CustomGroupStore
+	UserAttribute() UserAttributeStore
+	RetentionPolicy() RetentionPolicyStore
+	TermsOfService() TermsOfServiceStore
+	AnalyticsDaily() AnalyticsDailyStore
+	ProvisioningToken() ProvisioningTokenStore
+	TeamTemplate() TeamTemplateStore
+	InviteLink() InviteLinkStore
+	TeamInvitation() TeamInvitationStore
+	ShortPermalink() ShortPermalinkStore
+	ConfigurationHistory() ConfigurationHistoryStore
+	FilePublicLink() FilePublicLinkStore
+	FileStorageUsage() FileStorageUsageStore
+	BlockedDomain() BlockedDomainStore
+	WebSocketOutbox() WebSocketOutboxStore
+	PostIdempotencyKey() PostIdempotencyKeyStore
 	Plugin() PluginStore
 	MarkSystemRanUnitTests()
 	Close()
@@ -70,6 +121,10 @@ type Store interface {
 	TotalMasterDbConnections() int
 	TotalReadDbConnections() int
 	TotalSearchDbConnections() int
+	IsMasterHealthy() bool
+	IsReadOnly() bool
+	SetReadOnly(readOnly bool)
+	IsFullUnicodeSupported() bool
 }
 
 type TeamStore interface {
@@ -88,6 +143,7 @@ type TeamStore interface {
 	GetTeamsByUserId(userId string) StoreChannel
 	GetByInviteId(inviteId string) StoreChannel
 	PermanentDelete(teamId string) StoreChannel
+	GetDataCounts(teamId string) StoreChannel
 	AnalyticsTeamCount() StoreChannel
 	SaveMember(member *model.TeamMember, maxUsersPerTeam int) StoreChannel
 	UpdateMember(member *model.TeamMember) StoreChannel
@@ -96,6 +152,8 @@ type TeamStore interface {
 	GetMembersByIds(teamId string, userIds []string) StoreChannel
 	GetTotalMemberCount(teamId string) StoreChannel
 	GetActiveMemberCount(teamId string) StoreChannel
+	GetAllMemberCounts() StoreChannel
+	ReconcileMemberCounts(afterTeamId string, limit int) StoreChannel
 	GetTeamsForUser(userId string) StoreChannel
 	GetChannelUnreadsForAllTeams(excludeTeamId, userId string) StoreChannel
 	GetChannelUnreadsForTeam(teamId, userId string) StoreChannel
@@ -103,6 +161,10 @@ type TeamStore interface {
 	RemoveAllMembersByTeam(teamId string) StoreChannel
 	RemoveAllMembersByUser(userId string) StoreChannel
 	UpdateLastTeamIconUpdate(teamId string, curTime int64) StoreChannel
+	GetDefaultChannels(teamId string) StoreChannel
+	UpdateDefaultChannels(teamId string, channelIds []string) StoreChannel
+	GetFilteredWords(teamId string) StoreChannel
+	UpdateFilteredWords(teamId string, words []string) StoreChannel
 }
 
 type ChannelStore interface {
@@ -110,6 +172,7 @@ type ChannelStore interface {
 	CreateDirectChannel(userId string, otherUserId string) StoreChannel
 	SaveDirectChannel(channel *model.Channel, member1 *model.ChannelMember, member2 *model.ChannelMember) StoreChannel
 	Update(channel *model.Channel) StoreChannel
+	UpdateWithWebSocketEvent(channel *model.Channel, event *model.WebSocketOutboxEvent) StoreChannel
 	Get(id string, allowFromCache bool) StoreChannel
 	InvalidateChannel(id string)
 	InvalidateChannelByName(teamId, name string)
@@ -117,6 +180,11 @@ type ChannelStore interface {
 	Delete(channelId string, time int64) StoreChannel
 	Restore(channelId string, time int64) StoreChannel
 	SetDeleteAt(channelId string, deleteAt int64, updateAt int64) StoreChannel
+	SetPermanentDeleteAt(channelId string, permanentDeleteAt int64, updateAt int64) StoreChannel
+	GetDueForPermanentDeletion(before int64, limit int) StoreChannel
+	GetBatchForTeam(teamId string, limit int) StoreChannel
+	ArchiveDirectChannelsForUser(userId string, deleteAt int64) StoreChannel
+	RestoreDirectChannelsForUser(userId string, updateAt int64) StoreChannel
 	PermanentDeleteByTeam(teamId string) StoreChannel
 	PermanentDelete(channelId string) StoreChannel
 	GetByName(team_id string, name string, allowFromCache bool) StoreChannel
@@ -132,8 +200,10 @@ type ChannelStore interface {
 	GetTeamChannels(teamId string) StoreChannel
 	GetAll(teamId string) StoreChannel
 	GetForPost(postId string) StoreChannel
+	GetByIconEmojiName(emojiName string) StoreChannel
 	SaveMember(member *model.ChannelMember) StoreChannel
 	UpdateMember(member *model.ChannelMember) StoreChannel
+	UpdateMembers(members []*model.ChannelMember) StoreChannel
 	GetMembers(channelId string, offset, limit int) StoreChannel
 	GetMember(channelId string, userId string) StoreChannel
 	GetAllChannelMembersForUser(userId string, allowFromCache bool) StoreChannel
@@ -145,38 +215,153 @@ type ChannelStore interface {
 	InvalidateMemberCount(channelId string)
 	GetMemberCountFromCache(channelId string) int64
 	GetMemberCount(channelId string, allowFromCache bool) StoreChannel
+	GetGuestCount(channelId string) StoreChannel
+	ReconcileMemberCounts(afterChannelId string, limit int) StoreChannel
+	GetInactivePublicChannels(inactiveSince int64, afterChannelId string, limit int) StoreChannel
 	GetPinnedPosts(channelId string) StoreChannel
 	RemoveMember(channelId string, userId string) StoreChannel
 	PermanentDeleteMembersByUser(userId string) StoreChannel
 	PermanentDeleteMembersByChannel(channelId string) StoreChannel
 	UpdateLastViewedAt(channelIds []string, userId string) StoreChannel
+	GetViewedAt(channelIds []string) StoreChannel
+	UpdateViewedAt(userId string, views []*model.ChannelViewedAt) StoreChannel
 	IncrementMentionCount(channelId string, userId string) StoreChannel
+	IncrementMsgCountForUsersWithPreference(channelId string, category string, name string, value string) StoreChannel
 	AnalyticsTypeCount(teamId string, channelType string) StoreChannel
 	GetMembersForUser(teamId string, userId string) StoreChannel
 	AutocompleteInTeam(teamId string, term string) StoreChannel
 	SearchInTeam(teamId string, term string) StoreChannel
 	SearchMore(userId string, teamId string, term string) StoreChannel
+	SearchForUserInTeam(userId string, teamId string, term string) StoreChannel
 	GetMembersByIds(channelId string, userIds []string) StoreChannel
 	AnalyticsDeletedTypeCount(teamId string, channelType string) StoreChannel
+	AnalyticsFeedCount() StoreChannel
 	GetChannelUnread(channelId, userId string) StoreChannel
+	GetChannelUnreadsForUser(teamId, userId string) StoreChannel
+	UpdateLastViewedAtPost(unreadPost *model.Post, userId string, mentionCount int64) StoreChannel
+	UpdateLastReadPost(post *model.Post, userId string) StoreChannel
+	UpdateLastReadPostsToLatest(channelIds []string, userId string) StoreChannel
+	GetMembersWithUnreadMentions(channelId string, viewedBefore int64) StoreChannel
+	RemovePostsFromCounts(channelId string, posts []*model.Post, mentionCounts map[string]int64) StoreChannel
 	ClearCaches()
+
+	CreateInitialSidebarCategories(userId string, teamId string) StoreChannel
+	CreateSidebarCategory(category *model.SidebarCategoryWithChannels) StoreChannel
+	GetSidebarCategory(categoryId string) StoreChannel
+	GetSidebarCategories(userId string, teamId string) StoreChannel
+	UpdateSidebarCategories(userId string, teamId string, categories []*model.SidebarCategoryWithChannels) StoreChannel
+	UpdateSidebarCategoryOrder(userId string, teamId string, categoryOrder []string) StoreChannel
+	DeleteSidebarCategory(categoryId string) StoreChannel
+	RemoveChannelFromSidebarCategories(userId string, channelId string) StoreChannel
+	DeleteSidebarCategoriesForTeamMember(userId string, teamId string) StoreChannel
+
+	GetModeratedPermissions(channelId string) StoreChannel
+	UpdateModeratedPermissions(channelId string, moderated *model.ChannelModeratedPermissions) StoreChannel
+
+	SaveNameHistory(history *model.ChannelNameHistory, maxPerChannel int) StoreChannel
+	GetNameHistoryByName(teamId string, name string) StoreChannel
+	GetNameHistory(channelId string) StoreChannel
+	DeleteNameHistory(teamId string, name string) StoreChannel
 }
 
 type ChannelMemberHistoryStore interface {
-	LogJoinEvent(userId string, channelId string, joinTime int64) StoreChannel
-	LogLeaveEvent(userId string, channelId string, leaveTime int64) StoreChannel
+	LogJoinEvent(userId string, channelId string, joinTime int64, actorId string) StoreChannel
+	LogLeaveEvent(userId string, channelId string, leaveTime int64, actorId string, reason string) StoreChannel
 	GetUsersInChannelDuring(startTime int64, endTime int64, channelId string) StoreChannel
+	GetChannelHistory(channelId string, since int64, until int64, offset int, limit int) StoreChannel
+	GetLastLeaveTime(userId string, channelId string) StoreChannel
 	PermanentDeleteBatch(endTime int64, limit int64) StoreChannel
 }
 
+type ThreadStore interface {
+	Save(thread *model.Thread) StoreChannel
+	Update(thread *model.Thread) StoreChannel
+	Get(postId string) StoreChannel
+	Delete(postId string) StoreChannel
+	SaveMembership(membership *model.ThreadMembership) StoreChannel
+	UpdateMembership(membership *model.ThreadMembership) StoreChannel
+	GetMembershipForUser(userId string, postId string) StoreChannel
+	GetMembershipsForThread(postId string) StoreChannel
+	DeleteMembershipsForUserInChannel(userId string, channelId string) StoreChannel
+	IncrementMentionCount(userId string, postId string) StoreChannel
+	MarkAllAsReadForUser(userId string, teamId string, timestamp int64) StoreChannel
+	GetThreadForUser(userId string, postId string) StoreChannel
+	GetThreadsForUser(userId string, teamId string, offset int, limit int) StoreChannel
+}
+
+type CustomGroupStore interface {
+	Save(group *model.CustomGroup) StoreChannel
+	Update(group *model.CustomGroup) StoreChannel
+	Get(groupId string) StoreChannel
+	GetByName(name string) StoreChannel
+	GetByNames(names []string) StoreChannel
+	GetAll(offset int, limit int) StoreChannel
+	Search(term string, limit int) StoreChannel
+	Delete(groupId string) StoreChannel
+	SaveMember(member *model.GroupMember) StoreChannel
+	RemoveMember(groupId string, userId string) StoreChannel
+	GetMembers(groupId string) StoreChannel
+	PermanentDeleteMembersByUser(userId string) StoreChannel
+}
+
+type UserAttributeStore interface {
+	SaveField(field *model.UserAttributeField) StoreChannel
+	UpdateField(field *model.UserAttributeField) StoreChannel
+	GetField(fieldId string) StoreChannel
+	GetFields() StoreChannel
+	DeleteField(fieldId string) StoreChannel
+	SaveValue(attribute *model.UserAttribute) StoreChannel
+	DeleteValue(userId string, fieldId string) StoreChannel
+	GetValuesForUser(userId string) StoreChannel
+	GetValuesForUsers(userIds []string) StoreChannel
+	GetValuesForField(fieldId string) StoreChannel
+	PermanentDeleteByUser(userId string) StoreChannel
+}
+
+type RetentionPolicyStore interface {
+	Save(policy *model.RetentionPolicy) StoreChannel
+	Update(policy *model.RetentionPolicy) StoreChannel
+	Get(policyId string) StoreChannel
+	GetAll() StoreChannel
+	Delete(policyId string) StoreChannel
+	AddChannels(policyId string, channelIds []string) StoreChannel
+	RemoveChannels(policyId string, channelIds []string) StoreChannel
+	AddTeams(policyId string, teamIds []string) StoreChannel
+	RemoveTeams(policyId string, teamIds []string) StoreChannel
+	CountPostsForDeletion(policyId string, endTime int64, exemptPinned bool) StoreChannel
+	PermanentDeletePostsBatch(policyId string, endTime int64, exemptPinned bool, limit int64) StoreChannel
+	CountFilesForDeletion(policyId string, endTime int64, exemptPinned bool) StoreChannel
+	PermanentDeleteFilesBatch(policyId string, endTime int64, exemptPinned bool, limit int64) StoreChannel
+}
+
+type TermsOfServiceStore interface {
+	Save(termsOfService *model.TermsOfService) StoreChannel
+	Get(id string) StoreChannel
+	GetLatest() StoreChannel
+	SaveUserTermsOfService(userTermsOfService *model.UserTermsOfService) StoreChannel
+	GetUserTermsOfService(userId string) StoreChannel
+}
+
+type AnalyticsDailyStore interface {
+	RollupDay(day string, startTime int64, endTime int64) StoreChannel
+	GetSeries(teamId string, channelId string, metric string, fromDay string, toDay string) StoreChannel
+	GetLatestDay() StoreChannel
+	GetUserActivity(fromDay string, toDay string, minActivity int64, offset int, limit int) StoreChannel
+	PermanentDeleteBefore(day string) StoreChannel
+}
+
 type PostStore interface {
 	Save(post *model.Post) StoreChannel
+	SaveWithWebSocketEvent(post *model.Post) StoreChannel
 	Update(newPost *model.Post, oldPost *model.Post) StoreChannel
+	SaveHistory(history *model.PostHistory) StoreChannel
+	GetHistory(postId string) StoreChannel
 	Get(id string) StoreChannel
 	GetSingle(id string) StoreChannel
 	Delete(postId string, time int64) StoreChannel
 	PermanentDeleteByUser(userId string) StoreChannel
 	PermanentDeleteByChannel(channelId string) StoreChannel
+	PermanentDeleteByChannelBatch(channelId string, limit int64) StoreChannel
 	GetPosts(channelId string, offset int, limit int, allowFromCache bool) StoreChannel
 	GetFlaggedPosts(userId string, offset int, limit int) StoreChannel
 	GetFlaggedPostsForTeam(userId, teamId string, offset int, limit int) StoreChannel
@@ -194,6 +379,10 @@ type PostStore interface {
 	GetPostsCreatedAt(channelId string, time int64) StoreChannel
 	Overwrite(post *model.Post) StoreChannel
 	GetPostsByIds(postIds []string) StoreChannel
+	GetPostsByUser(userId string, offset int, limit int) StoreChannel
+	GetPostsWithMessageContaining(term string, afterId string, limit int) StoreChannel
+	GetPostsWithPropsLargerThan(size int, afterId string, limit int) StoreChannel
+	GetPostsForChannelExport(channelId string, afterCreateAt int64, afterId string, limit int) StoreChannel
 	GetPostsBatchForIndexing(startTime int64, endTime int64, limit int) StoreChannel
 	PermanentDeleteBatch(endTime int64, limit int64) StoreChannel
 	GetOldest() StoreChannel
@@ -216,6 +405,7 @@ type UserStore interface {
 	InvalidateProfilesInChannelCache(channelId string)
 	GetProfilesInChannel(channelId string, offset int, limit int) StoreChannel
 	GetProfilesInChannelByStatus(channelId string, offset int, limit int) StoreChannel
+	GetBotsInChannel(channelId string) StoreChannel
 	GetAllProfilesInChannel(channelId string, allowFromCache bool) StoreChannel
 	GetProfilesNotInChannel(teamId string, channelId string, offset int, limit int) StoreChannel
 	GetProfilesWithoutTeam(offset int, limit int) StoreChannel
@@ -227,7 +417,10 @@ type UserStore interface {
 	GetByEmail(email string) StoreChannel
 	GetByAuth(authData *string, authService string) StoreChannel
 	GetAllUsingAuthService(authService string) StoreChannel
+	GetUsingAuthService(authService string, authData string, offset int, limit int) StoreChannel
+	CountUsingAuthService(authService string, authData string) StoreChannel
 	GetByUsername(username string) StoreChannel
+	GetByFullNames(fullNames []string) StoreChannel
 	GetForLogin(loginId string, allowSignInWithUsername, allowSignInWithEmail bool) StoreChannel
 	VerifyEmail(userId string) StoreChannel
 	GetEtagForAllProfiles() StoreChannel
@@ -248,9 +441,23 @@ type UserStore interface {
 	SearchNotInChannel(teamId string, channelId string, term string, options map[string]bool) StoreChannel
 	SearchWithoutTeam(term string, options map[string]bool) StoreChannel
 	AnalyticsGetInactiveUsersCount() StoreChannel
+	AnalyticsBotCount() StoreChannel
 	AnalyticsGetSystemAdminCount() StoreChannel
 	GetProfilesNotInTeam(teamId string, offset int, limit int) StoreChannel
 	GetEtagForProfilesNotInTeam(teamId string) StoreChannel
+	GetProfilesInUserChannels(userId string, teamId string, offset int, limit int) StoreChannel
+	SearchInUserChannels(userId string, teamId string, term string, options map[string]bool) StoreChannel
+	GetIdsSharingChannelsWithUser(userId string, userIds []string) StoreChannel
+	PromoteGuestToUser(userId string) StoreChannel
+	DemoteUserToGuest(userId string) StoreChannel
+	UpdateDeactivateAt(userId string, deactivateAt int64) StoreChannel
+	GetDueForDeactivation(deactivateBefore int64) StoreChannel
+	UpdateLastSeenAt(userId string, lastSeenAt int64) StoreChannel
+	GetProfilesWithFilter(filter *model.UserFilter, offset int, limit int) StoreChannel
+	CountWithFilter(filter *model.UserFilter) StoreChannel
+	SaveUsernameHistory(history *model.UsernameHistory, maxPerUser int) StoreChannel
+	GetUsernameHistoryByUsername(username string) StoreChannel
+	DeleteUsernameHistory(userId string) StoreChannel
 }
 
 type SessionStore interface {
@@ -262,10 +469,14 @@ type SessionStore interface {
 	RemoveAllSessions() StoreChannel
 	PermanentDeleteSessionsByUser(teamId string) StoreChannel
 	UpdateLastActivityAt(sessionId string, time int64) StoreChannel
+	UpdateLastActivityAtBatch(lastActivityAt map[string]int64) StoreChannel
 	UpdateRoles(userId string, roles string) StoreChannel
 	UpdateDeviceId(id string, deviceId string, expiresAt int64) StoreChannel
+	UpdateProps(sessionId string, props model.StringMap) StoreChannel
+	GetImpersonationSessions(impersonatorSessionId string) StoreChannel
 	AnalyticsSessionCount() StoreChannel
-	Cleanup(expiryTime int64, batchSize int64)
+	PermanentDeleteExpiredBatch(expiredBefore int64, limit int64) StoreChannel
+	PermanentDeleteOrphanedBatch(limit int64) StoreChannel
 }
 
 type AuditStore interface {
@@ -273,6 +484,8 @@ type AuditStore interface {
 	Get(user_id string, offset int, limit int) StoreChannel
 	PermanentDeleteByUser(userId string) StoreChannel
 	PermanentDeleteBatch(endTime int64, limit int64) StoreChannel
+	GetCountBefore(endTime int64) StoreChannel
+	GetMaxRowsCutoff(maxRows int64) StoreChannel
 }
 
 type ClusterDiscoveryStore interface {
@@ -291,6 +504,7 @@ type ComplianceStore interface {
 	GetAll(offset, limit int) StoreChannel
 	ComplianceExport(compliance *model.Compliance) StoreChannel
 	MessageExport(after int64, limit int) StoreChannel
+	ExportPosts(startTime int64, endTime int64, afterCreateAt int64, afterId string, limit int) StoreChannel
 }
 
 type OAuthStore interface {
@@ -310,6 +524,7 @@ type OAuthStore interface {
 	GetAccessData(token string) StoreChannel
 	GetAccessDataByUserForApp(userId, clientId string) StoreChannel
 	GetAccessDataByRefreshToken(token string) StoreChannel
+	GetAccessDataByPreviousRefreshToken(token string) StoreChannel
 	GetPreviousAccessData(userId, clientId string) StoreChannel
 	RemoveAccessData(token string) StoreChannel
 }
@@ -333,6 +548,7 @@ type WebhookStore interface {
 	DeleteIncoming(webhookId string, time int64) StoreChannel
 	PermanentDeleteIncomingByChannel(channelId string) StoreChannel
 	PermanentDeleteIncomingByUser(userId string) StoreChannel
+	PermanentDeleteIncomingByTeam(teamId string) StoreChannel
 
 	SaveOutgoing(webhook *model.OutgoingWebhook) StoreChannel
 	GetOutgoing(id string) StoreChannel
@@ -342,6 +558,7 @@ type WebhookStore interface {
 	DeleteOutgoing(webhookId string, time int64) StoreChannel
 	PermanentDeleteOutgoingByChannel(channelId string) StoreChannel
 	PermanentDeleteOutgoingByUser(userId string) StoreChannel
+	PermanentDeleteOutgoingByTeam(teamId string) StoreChannel
 	UpdateOutgoing(hook *model.OutgoingWebhook) StoreChannel
 
 	AnalyticsIncomingCount(teamId string) StoreChannel
@@ -373,13 +590,16 @@ type PreferenceStore interface {
 	Save(preferences *model.Preferences) StoreChannel
 	Get(userId string, category string, name string) StoreChannel
 	GetCategory(userId string, category string) StoreChannel
+	GetUserIdsByCategoryAndName(category string, name string) StoreChannel
 	GetAll(userId string) StoreChannel
+	GetPage(userId string, category string, offset int, limit int) StoreChannel
 	Delete(userId, category, name string) StoreChannel
 	DeleteCategory(userId string, category string) StoreChannel
 	DeleteCategoryAndName(category string, name string) StoreChannel
 	PermanentDeleteByUser(userId string) StoreChannel
 	IsFeatureEnabled(feature, userId string) StoreChannel
 	CleanupFlagsBatch(limit int64) StoreChannel
+	CleanupChannelsBatch(limit int64) StoreChannel
 }
 
 type LicenseStore interface {
@@ -391,6 +611,7 @@ type TokenStore interface {
 	Save(recovery *model.Token) StoreChannel
 	Delete(token string) StoreChannel
 	GetByToken(token string) StoreChannel
+	GetAllTokensByType(tokenType string) StoreChannel
 	Cleanup()
 }
 
@@ -398,9 +619,13 @@ type EmojiStore interface {
 	Save(emoji *model.Emoji) StoreChannel
 	Get(id string, allowFromCache bool) StoreChannel
 	GetByName(name string) StoreChannel
+	GetMultipleByName(names []string) StoreChannel
 	GetList(offset, limit int, sort string) StoreChannel
 	Delete(id string, time int64) StoreChannel
 	Search(name string, prefixOnly bool, limit int) StoreChannel
+	RollUpStats(updateAt int64) StoreChannel
+	GetStats(offset, limit int) StoreChannel
+	GetUnused(before int64) StoreChannel
 }
 
 type StatusStore interface {
@@ -419,19 +644,34 @@ type FileInfoStore interface {
 	Save(info *model.FileInfo) StoreChannel
 	Get(id string) StoreChannel
 	GetByPath(path string) StoreChannel
+	GetByHash(hash string, size int64, creatorId string) StoreChannel
 	GetForPost(postId string, readFromMaster bool, allowFromCache bool) StoreChannel
+	GetForPosts(postIds []string) StoreChannel
+	GetForUser(userId string) StoreChannel
+	GetWithFilter(filter *model.FileInfoFilter, offset int, limit int) StoreChannel
+	GetStatsWithFilter(filter *model.FileInfoFilter) StoreChannel
 	InvalidateFileInfosForPostCache(postId string)
 	AttachToPost(fileId string, postId string) StoreChannel
 	DeleteForPost(postId string) StoreChannel
 	PermanentDelete(fileId string) StoreChannel
 	PermanentDeleteBatch(endTime int64, limit int64) StoreChannel
+	PermanentDeleteForChannelBatch(channelId string, limit int64) StoreChannel
+	AnalyticsCount() StoreChannel
+	GetBatchAfterId(afterId string, limit int) StoreChannel
+	GetReferencedPaths(paths []string) StoreChannel
+	SetMissingAt(fileIds []string, missingAt int64) StoreChannel
+	SetContentType(fileId string, contentType string) StoreChannel
 	ClearCaches()
 }
 
 type ReactionStore interface {
 	Save(reaction *model.Reaction) StoreChannel
+	SaveWithWebSocketEvent(reaction *model.Reaction, event *model.WebSocketOutboxEvent) StoreChannel
 	Delete(reaction *model.Reaction) StoreChannel
+	DeleteWithWebSocketEvent(reaction *model.Reaction, event *model.WebSocketOutboxEvent) StoreChannel
 	GetForPost(postId string, allowFromCache bool) StoreChannel
+	GetForUser(userId string) StoreChannel
+	GetCountsForPosts(postIds []string, userId string) StoreChannel
 	DeleteAllWithEmojiName(emojiName string) StoreChannel
 	PermanentDeleteBatch(endTime int64, limit int64) StoreChannel
 }
@@ -449,6 +689,9 @@ type JobStore interface {
 	GetNewestJobByStatusAndType(status string, jobType string) StoreChannel
 	GetCountByStatusAndType(status string, jobType string) StoreChannel
 	Delete(id string) StoreChannel
+	PermanentDeleteFinishedBatch(endTime int64, limit int64) StoreChannel
+	GetFinishedCountBefore(endTime int64) StoreChannel
+	GetFinishedMaxRowsCutoff(maxRows int64) StoreChannel
 }
 
 type UserAccessTokenStore interface {
@@ -464,6 +707,94 @@ type UserAccessTokenStore interface {
 	UpdateTokenDisable(tokenId string) StoreChannel
 }
 
+type ProvisioningTokenStore interface {
+	Save(token *model.ProvisioningToken) StoreChannel
+	Get(tokenId string) StoreChannel
+	GetByToken(tokenString string) StoreChannel
+	GetAll(offset int, limit int) StoreChannel
+	Delete(tokenId string) StoreChannel
+}
+
+type TeamTemplateStore interface {
+	Save(template *model.TeamTemplate) StoreChannel
+	Update(template *model.TeamTemplate) StoreChannel
+	Get(templateId string) StoreChannel
+	GetByName(name string) StoreChannel
+	GetAll(offset int, limit int) StoreChannel
+	Delete(templateId string) StoreChannel
+}
+
+type InviteLinkStore interface {
+	Save(link *model.InviteLink) StoreChannel
+	Get(linkId string) StoreChannel
+	GetForTeam(teamId string) StoreChannel
+	Revoke(linkId string, deleteAt int64) StoreChannel
+	Redeem(linkId string, userId string, now int64) StoreChannel
+	GetRedemptions(linkId string) StoreChannel
+}
+
+type TeamInvitationStore interface {
+	Save(invitation *model.TeamInvitation) StoreChannel
+	Get(invitationId string) StoreChannel
+	GetForTeam(teamId string, offset int, limit int) StoreChannel
+	UpdateStatus(invitationId string, status string, reason string, updateAt int64) StoreChannel
+	MarkBounced(email string, reason string, updateAt int64) StoreChannel
+}
+
+type ShortPermalinkStore interface {
+	Save(link *model.ShortPermalink) StoreChannel
+	Get(code string) StoreChannel
+	GetLatestForPost(postId string, teamId string, expireAfter int64) StoreChannel
+	PermanentDeleteExpired(expiredBefore int64) StoreChannel
+}
+
+type ConfigurationHistoryStore interface {
+	Save(history *model.ConfigurationHistory) StoreChannel
+	Get(id string) StoreChannel
+	GetPage(offset int, limit int) StoreChannel
+	GetPrevious(history *model.ConfigurationHistory) StoreChannel
+	UpdateSecrets(secrets map[string]string) StoreChannel
+	PermanentDeleteAllButLatest(keep int) StoreChannel
+}
+
+type FilePublicLinkStore interface {
+	Save(link *model.FilePublicLink) StoreChannel
+	Get(linkId string) StoreChannel
+	GetActiveForUser(userId string, now int64) StoreChannel
+	GetActiveForChannel(channelId string, now int64) StoreChannel
+	Revoke(linkId string, deleteAt int64) StoreChannel
+	CountDownload(linkId string, now int64) StoreChannel
+}
+
+type FileStorageUsageStore interface {
+	Get(id string) StoreChannel
+	Reserve(userId string, teamId string, size int64, userQuota int64, teamQuota int64) StoreChannel
+	Release(userId string, teamId string, size int64) StoreChannel
+	GetTop(usageType string, offset int, limit int) StoreChannel
+	SetExempt(userId string, exempt bool) StoreChannel
+	Reconcile(usageType string, afterId string, limit int) StoreChannel
+}
+
+type BlockedDomainStore interface {
+	Save(domain *model.BlockedDomain) StoreChannel
+	GetAll() StoreChannel
+	Delete(domain string) StoreChannel
+}
+
+type WebSocketOutboxStore interface {
+	GetUnsent(after int64, before int64, limit int) StoreChannel
+	MarkSent(id string, sentAt int64) StoreChannel
+	PermanentDeleteBatch(endTime int64, limit int64) StoreChannel
+}
+
+type PostIdempotencyKeyStore interface {
+	Reserve(key *model.PostIdempotencyKey, expiredBefore int64) StoreChannel
+	Get(userId string, idempotencyKey string) StoreChannel
+	SetPostId(userId string, idempotencyKey string, postId string) StoreChannel
+	Delete(userId string, idempotencyKey string) StoreChannel
+	PermanentDeleteBatch(endTime int64, limit int64) StoreChannel
+}
+
 type PluginStore interface {
 	SaveOrUpdate(keyVal *model.PluginKeyValue) StoreChannel
 	Get(pluginId, key string) StoreChannel
@@ -0,0 +1,274 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package storetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func TestAnalyticsDailyStore(t *testing.T, ss store.Store) {
+	t.Run("RollupDay", func(t *testing.T) { testAnalyticsDailyStoreRollupDay(t, ss) })
+	t.Run("GetUserActivity", func(t *testing.T) { testAnalyticsDailyStoreGetUserActivity(t, ss) })
+	t.Run("PermanentDeleteBefore", func(t *testing.T) { testAnalyticsDailyStorePermanentDeleteBefore(t, ss) })
+}
+
+func analyticsDailyTestDay(day int) (string, int64, int64) {
+	start := time.Date(2001, time.March, day, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 1).Add(-time.Millisecond)
+	return start.Format(model.ANALYTICS_DAY_FORMAT), start.UnixNano() / int64(time.Millisecond), end.UnixNano() / int64(time.Millisecond)
+}
+
+func testAnalyticsDailyStoreRollupDay(t *testing.T, ss store.Store) {
+	team := store.Must(ss.Team().Save(&model.Team{
+		DisplayName: "DisplayName",
+		Name:        "zz" + model.NewId() + "b",
+		Email:       model.NewId() + "@nowhere.com",
+		Type:        model.TEAM_OPEN,
+	})).(*model.Team)
+
+	channel1 := store.Must(ss.Channel().Save(&model.Channel{
+		TeamId:      team.Id,
+		DisplayName: "Display " + model.NewId(),
+		Name:        "zz" + model.NewId() + "b",
+		Type:        model.CHANNEL_OPEN,
+	}, -1)).(*model.Channel)
+
+	channel2 := store.Must(ss.Channel().Save(&model.Channel{
+		TeamId:      team.Id,
+		DisplayName: "Display " + model.NewId(),
+		Name:        "zz" + model.NewId() + "b",
+		Type:        model.CHANNEL_PRIVATE,
+	}, -1)).(*model.Channel)
+
+	userId1 := model.NewId()
+	userId2 := model.NewId()
+
+	day1, start1, end1 := analyticsDailyTestDay(4)
+	day2, start2, end2 := analyticsDailyTestDay(5)
+	day3, start3, end3 := analyticsDailyTestDay(6)
+
+	// day 1 has three posts by two users in the first channel and one in the second
+	for _, post := range []*model.Post{
+		{ChannelId: channel1.Id, UserId: userId1, CreateAt: start1},
+		{ChannelId: channel1.Id, UserId: userId1, CreateAt: start1 + 1000},
+		{ChannelId: channel1.Id, UserId: userId2, CreateAt: end1},
+		{ChannelId: channel2.Id, UserId: userId2, CreateAt: start1 + 2000},
+		{ChannelId: channel2.Id, UserId: userId1, CreateAt: start2 + 1000},
+	} {
+		post.Message = "message " + model.NewId()
+		store.Must(ss.Post().Save(post))
+	}
+
+	// and one user joins the second channel on day 2
+	store.Must(ss.ChannelMemberHistory().LogJoinEvent(userId1, channel2.Id, start2+5000, userId2))
+
+	store.Must(ss.AnalyticsDaily().RollupDay(day1, start1, end1))
+	store.Must(ss.AnalyticsDaily().RollupDay(day2, start2, end2))
+	store.Must(ss.AnalyticsDaily().RollupDay(day3, start3, end3))
+
+	getSeries := func(channelId string, metric string) map[string]int64 {
+		result := <-ss.AnalyticsDaily().GetSeries(team.Id, channelId, metric, day1, day3)
+		require.Nil(t, result.Err)
+
+		values := map[string]int64{}
+		for _, daily := range result.Data.([]*model.AnalyticsDaily) {
+			values[daily.Day] = daily.Value
+		}
+		return values
+	}
+
+	assert.Equal(t, map[string]int64{day1: 3}, getSeries(channel1.Id, model.ANALYTICS_METRIC_POSTS))
+	assert.Equal(t, map[string]int64{day1: 2}, getSeries(channel1.Id, model.ANALYTICS_METRIC_ACTIVE_USERS))
+	assert.Equal(t, map[string]int64{}, getSeries(channel1.Id, model.ANALYTICS_METRIC_NEW_MEMBERS))
+
+	assert.Equal(t, map[string]int64{day1: 1, day2: 1}, getSeries(channel2.Id, model.ANALYTICS_METRIC_POSTS))
+	assert.Equal(t, map[string]int64{day2: 1}, getSeries(channel2.Id, model.ANALYTICS_METRIC_NEW_MEMBERS))
+
+	assert.Equal(t, map[string]int64{day1: 4, day2: 1}, getSeries("", model.ANALYTICS_METRIC_POSTS))
+	assert.Equal(t, map[string]int64{day1: 2, day2: 1}, getSeries("", model.ANALYTICS_METRIC_ACTIVE_USERS))
+
+	// the series is ordered by day
+	result := <-ss.AnalyticsDaily().GetSeries(team.Id, "", model.ANALYTICS_METRIC_POSTS, day1, day3)
+	require.Nil(t, result.Err)
+	series := result.Data.([]*model.AnalyticsDaily)
+	require.Len(t, series, 2)
+	assert.Equal(t, day1, series[0].Day)
+	assert.Equal(t, day2, series[1].Day)
+
+	// the system wide values exist even for days without posts
+	result = <-ss.AnalyticsDaily().GetSeries("", "", model.ANALYTICS_METRIC_POSTS, day3, day3)
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]*model.AnalyticsDaily), 1)
+
+	result = <-ss.AnalyticsDaily().GetLatestDay()
+	require.Nil(t, result.Err)
+	assert.True(t, result.Data.(string) >= day3)
+
+	// rolling a day up again replaces its values
+	store.Must(ss.Post().Save(&model.Post{ChannelId: channel1.Id, UserId: userId2, CreateAt: start1 + 3000, Message: "message"}))
+	store.Must(ss.AnalyticsDaily().RollupDay(day1, start1, end1))
+	assert.Equal(t, map[string]int64{day1: 4}, getSeries(channel1.Id, model.ANALYTICS_METRIC_POSTS))
+}
+
+func testAnalyticsDailyStoreGetUserActivity(t *testing.T, ss store.Store) {
+	team := store.Must(ss.Team().Save(&model.Team{
+		DisplayName: "DisplayName",
+		Name:        "zz" + model.NewId() + "b",
+		Email:       model.NewId() + "@nowhere.com",
+		Type:        model.TEAM_OPEN,
+	})).(*model.Team)
+
+	var channels []*model.Channel
+	for _, channelType := range []string{model.CHANNEL_OPEN, model.CHANNEL_PRIVATE, model.CHANNEL_OPEN} {
+		channels = append(channels, store.Must(ss.Channel().Save(&model.Channel{
+			TeamId:      team.Id,
+			DisplayName: "Display " + model.NewId(),
+			Name:        "zz" + model.NewId() + "b",
+			Type:        channelType,
+		}, -1)).(*model.Channel))
+	}
+
+	var users []*model.User
+	for i := 0; i < 3; i++ {
+		users = append(users, store.Must(ss.User().Save(&model.User{
+			Email:    model.NewId(),
+			Username: "u" + model.NewId(),
+		})).(*model.User))
+	}
+	poster, reactor, joiner := users[0], users[1], users[2]
+
+	// the raw data, tallied as it's seeded to check the rollups against
+	expected := map[string]*model.AnalyticsUserActivity{}
+	for _, user := range users {
+		expected[user.Id] = &model.AnalyticsUserActivity{UserId: user.Id, Username: user.Username}
+	}
+
+	var days []string
+	for i := 0; i < 7; i++ {
+		day, start, end := analyticsDailyTestDay(10 + i)
+		days = append(days, day)
+
+		// the poster posts once more each day, replying to their first post of the day after the first day
+		var root *model.Post
+		for j := 0; j <= i; j++ {
+			post := &model.Post{ChannelId: channels[j%2].Id, UserId: poster.Id, CreateAt: start + int64(j)*1000, Message: "message " + model.NewId()}
+			if root != nil && i > 0 {
+				post.RootId = root.Id
+				post.ParentId = root.Id
+				expected[poster.Id].Replies++
+			}
+			post = store.Must(ss.Post().Save(post)).(*model.Post)
+			if root == nil {
+				root = post
+			}
+			expected[poster.Id].Posts++
+			expected[poster.Id].LastActivityAt = post.CreateAt
+		}
+
+		// system messages aren't activity
+		store.Must(ss.Post().Save(&model.Post{ChannelId: channels[0].Id, UserId: poster.Id, CreateAt: start + 500, Type: model.POST_JOIN_CHANNEL, Message: "joined"}))
+
+		// the reactor reacts to the first post of every other day, at the end of the day
+		if i%2 == 0 {
+			store.Must(ss.Reaction().Save(&model.Reaction{UserId: reactor.Id, PostId: root.Id, EmojiName: "smile", CreateAt: end}))
+			store.Must(ss.Reaction().Save(&model.Reaction{UserId: reactor.Id, PostId: root.Id, EmojiName: "tada", CreateAt: end - 1000}))
+			expected[reactor.Id].Reactions += 2
+			expected[reactor.Id].LastActivityAt = end
+		}
+
+		// and the joiner joins a channel on each of the first three days
+		if i < 3 {
+			store.Must(ss.ChannelMemberHistory().LogJoinEvent(joiner.Id, channels[i].Id, start+2000, ""))
+			expected[joiner.Id].ChannelsJoined++
+			expected[joiner.Id].LastActivityAt = start + 2000
+		}
+
+		store.Must(ss.AnalyticsDaily().RollupDay(day, start, end))
+	}
+
+	getUserActivity := func(fromDay string, toDay string, minActivity int64) map[string]*model.AnalyticsUserActivity {
+		result := <-ss.AnalyticsDaily().GetUserActivity(fromDay, toDay, minActivity, 0, 1000)
+		require.Nil(t, result.Err)
+
+		activity := map[string]*model.AnalyticsUserActivity{}
+		for _, user := range result.Data.([]*model.AnalyticsUserActivity) {
+			if _, ok := expected[user.UserId]; ok {
+				activity[user.UserId] = user
+			}
+		}
+		return activity
+	}
+
+	activity := getUserActivity(days[0], days[6], 0)
+	assert.Equal(t, expected, activity)
+	assert.Equal(t, int64(28), activity[poster.Id].Posts)
+	assert.Equal(t, int64(21), activity[poster.Id].Replies)
+	assert.Equal(t, int64(8), activity[reactor.Id].Reactions)
+	assert.Equal(t, int64(3), activity[joiner.Id].ChannelsJoined)
+
+	t.Run("part of the week", func(t *testing.T) {
+		activity := getUserActivity(days[1], days[2], 0)
+		require.Len(t, activity, 3)
+		assert.Equal(t, int64(5), activity[poster.Id].Posts)
+		assert.Equal(t, int64(3), activity[poster.Id].Replies)
+		assert.Equal(t, int64(2), activity[reactor.Id].Reactions)
+		assert.Equal(t, int64(2), activity[joiner.Id].ChannelsJoined)
+	})
+
+	t.Run("minimum activity", func(t *testing.T) {
+		activity := getUserActivity(days[0], days[6], 1)
+		assert.Len(t, activity, 2)
+		assert.Nil(t, activity[joiner.Id], "joining channels alone shouldn't count as activity")
+
+		activity = getUserActivity(days[0], days[6], 9)
+		assert.Len(t, activity, 1)
+		assert.NotNil(t, activity[poster.Id])
+	})
+
+	t.Run("ordered by posts", func(t *testing.T) {
+		result := <-ss.AnalyticsDaily().GetUserActivity(days[0], days[6], 1, 0, 1000)
+		require.Nil(t, result.Err)
+
+		all := result.Data.([]*model.AnalyticsUserActivity)
+		for i := 1; i < len(all); i++ {
+			assert.True(t, all[i-1].Posts >= all[i].Posts)
+		}
+	})
+
+	t.Run("rolling a day up again replaces its activity", func(t *testing.T) {
+		day, start, end := analyticsDailyTestDay(16)
+		store.Must(ss.Reaction().Save(&model.Reaction{UserId: joiner.Id, PostId: model.NewId(), EmojiName: "smile", CreateAt: start}))
+		store.Must(ss.AnalyticsDaily().RollupDay(day, start, end))
+		store.Must(ss.AnalyticsDaily().RollupDay(day, start, end))
+
+		activity := getUserActivity(days[0], days[6], 0)
+		assert.Equal(t, int64(1), activity[joiner.Id].Reactions)
+		assert.Equal(t, expected[poster.Id], activity[poster.Id])
+	})
+}
+
+func testAnalyticsDailyStorePermanentDeleteBefore(t *testing.T, ss store.Store) {
+	day1, start1, end1 := analyticsDailyTestDay(1)
+	day2, start2, end2 := analyticsDailyTestDay(2)
+
+	store.Must(ss.AnalyticsDaily().RollupDay(day1, start1, end1))
+	store.Must(ss.AnalyticsDaily().RollupDay(day2, start2, end2))
+
+	result := <-ss.AnalyticsDaily().PermanentDeleteBefore(day2)
+	require.Nil(t, result.Err)
+	assert.True(t, result.Data.(int64) >= 3)
+
+	result = <-ss.AnalyticsDaily().GetSeries("", "", model.ANALYTICS_METRIC_POSTS, day1, day2)
+	require.Nil(t, result.Err)
+	series := result.Data.([]*model.AnalyticsDaily)
+	require.Len(t, series, 1)
+	assert.Equal(t, day2, series[0].Day)
+}
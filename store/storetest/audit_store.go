@@ -14,6 +14,7 @@ import (
 func TestAuditStore(t *testing.T, ss store.Store) {
 	t.Run("", func(t *testing.T) { testAuditStore(t, ss) })
 	t.Run("PermanentDeleteBatch", func(t *testing.T) { testAuditStorePermanentDeleteBatch(t, ss) })
+	t.Run("GetMaxRowsCutoff", func(t *testing.T) { testAuditStoreGetMaxRowsCutoff(t, ss) })
 }
 
 func testAuditStore(t *testing.T, ss store.Store) {
@@ -89,3 +90,31 @@ func testAuditStorePermanentDeleteBatch(t *testing.T, ss store.Store) {
 		t.Fatal(r2.Err)
 	}
 }
+
+func testAuditStoreGetMaxRowsCutoff(t *testing.T, ss store.Store) {
+	userId := model.NewId()
+
+	var audits []*model.Audit
+	for i := 0; i < 3; i++ {
+		time.Sleep(10 * time.Millisecond)
+		audit := &model.Audit{UserId: userId, IpAddress: "ipaddress", Action: "Action"}
+		store.Must(ss.Audit().Save(audit))
+		audits = append(audits, audit)
+	}
+
+	// These are the newest audits, so the cutoff for keeping two of them is the time of the second
+	cutoff := store.Must(ss.Audit().GetMaxRowsCutoff(2)).(int64)
+	if cutoff != audits[1].CreateAt {
+		t.Fatal("Expected the cutoff to be the time of the second audit. Got ", cutoff)
+	}
+
+	if count := store.Must(ss.Audit().GetCountBefore(cutoff)).(int64); count < 1 {
+		t.Fatal("Expected at least 1 audit before the cutoff. Got ", count)
+	}
+
+	if cutoff := store.Must(ss.Audit().GetMaxRowsCutoff(1000000000)).(int64); cutoff != 0 {
+		t.Fatal("Expected no cutoff for more rows than there are. Got ", cutoff)
+	}
+
+	store.Must(ss.Audit().PermanentDeleteByUser(userId))
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package storetest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func TestBlockedDomainStore(t *testing.T, ss store.Store) {
+	t.Run("SaveAndGetAll", func(t *testing.T) { testBlockedDomainStoreSaveAndGetAll(t, ss) })
+	t.Run("Delete", func(t *testing.T) { testBlockedDomainStoreDelete(t, ss) })
+}
+
+func testBlockedDomainStoreSaveAndGetAll(t *testing.T, ss store.Store) {
+	domain1 := store.Must(ss.BlockedDomain().Save(&model.BlockedDomain{Domain: model.NewId() + ".example.com", CreatorId: model.NewId()})).(*model.BlockedDomain)
+	domain2 := store.Must(ss.BlockedDomain().Save(&model.BlockedDomain{Domain: "*." + model.NewId() + ".example.com", CreatorId: model.NewId()})).(*model.BlockedDomain)
+	assert.NotZero(t, domain1.CreateAt)
+
+	domains := store.Must(ss.BlockedDomain().GetAll()).([]*model.BlockedDomain)
+	assert.Contains(t, domains, domain1)
+	assert.Contains(t, domains, domain2)
+
+	result := <-ss.BlockedDomain().Save(&model.BlockedDomain{Domain: domain1.Domain, CreatorId: model.NewId()})
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "store.sql_blocked_domain.save.exists.app_error", result.Err.Id)
+	assert.Equal(t, http.StatusBadRequest, result.Err.StatusCode)
+
+	result = <-ss.BlockedDomain().Save(&model.BlockedDomain{Domain: "Example.COM", CreatorId: model.NewId()})
+	require.NotNil(t, result.Err, "domains have to be normalized before they're saved")
+	assert.Equal(t, "model.blocked_domain.is_valid.domain.app_error", result.Err.Id)
+}
+
+func testBlockedDomainStoreDelete(t *testing.T, ss store.Store) {
+	domain := store.Must(ss.BlockedDomain().Save(&model.BlockedDomain{Domain: model.NewId() + ".example.com", CreatorId: model.NewId()})).(*model.BlockedDomain)
+
+	require.Nil(t, (<-ss.BlockedDomain().Delete(domain.Domain)).Err)
+	assert.NotContains(t, store.Must(ss.BlockedDomain().GetAll()).([]*model.BlockedDomain), domain)
+
+	require.Nil(t, (<-ss.BlockedDomain().Delete(domain.Domain)).Err, "deleting a domain that isn't blocked should do nothing")
+}
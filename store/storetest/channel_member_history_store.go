@@ -19,6 +19,8 @@ func TestChannelMemberHistoryStore(t *testing.T, ss store.Store) {
 	t.Run("TestGetUsersInChannelAtChannelMemberHistory", func(t *testing.T) { testGetUsersInChannelAtChannelMemberHistory(t, ss) })
 	t.Run("TestGetUsersInChannelAtChannelMembers", func(t *testing.T) { testGetUsersInChannelAtChannelMembers(t, ss) })
 	t.Run("TestPermanentDeleteBatch", func(t *testing.T) { testPermanentDeleteBatch(t, ss) })
+	t.Run("TestGetChannelHistory", func(t *testing.T) { testGetChannelHistory(t, ss) })
+	t.Run("TestGetLastLeaveTime", func(t *testing.T) { testGetLastLeaveTime(t, ss) })
 }
 
 func testLogJoinEvent(t *testing.T, ss store.Store) {
@@ -40,7 +42,7 @@ func testLogJoinEvent(t *testing.T, ss store.Store) {
 	user = *store.Must(ss.User().Save(&user)).(*model.User)
 
 	// log a join event
-	result := <-ss.ChannelMemberHistory().LogJoinEvent(user.Id, channel.Id, model.GetMillis())
+	result := <-ss.ChannelMemberHistory().LogJoinEvent(user.Id, channel.Id, model.GetMillis(), user.Id)
 	assert.Nil(t, result.Err)
 }
 
@@ -63,10 +65,10 @@ func testLogLeaveEvent(t *testing.T, ss store.Store) {
 	user = *store.Must(ss.User().Save(&user)).(*model.User)
 
 	// log a join event, followed by a leave event
-	result := <-ss.ChannelMemberHistory().LogJoinEvent(user.Id, channel.Id, model.GetMillis())
+	result := <-ss.ChannelMemberHistory().LogJoinEvent(user.Id, channel.Id, model.GetMillis(), user.Id)
 	assert.Nil(t, result.Err)
 
-	result = <-ss.ChannelMemberHistory().LogLeaveEvent(user.Id, channel.Id, model.GetMillis())
+	result = <-ss.ChannelMemberHistory().LogLeaveEvent(user.Id, channel.Id, model.GetMillis(), user.Id, model.CHANNEL_MEMBER_LEAVE_REASON_LEFT)
 	assert.Nil(t, result.Err)
 }
 
@@ -93,13 +95,13 @@ func testGetUsersInChannelAtChannelMemberHistory(t *testing.T, ss store.Store) {
 	// us from looking in the ChannelMembers table for data that isn't found in the ChannelMemberHistory table
 	leaveTime := model.GetMillis() - 20000
 	joinTime := leaveTime - 10000
-	store.Must(ss.ChannelMemberHistory().LogJoinEvent(user.Id, channel.Id, joinTime))
-	store.Must(ss.ChannelMemberHistory().LogLeaveEvent(user.Id, channel.Id, leaveTime))
+	store.Must(ss.ChannelMemberHistory().LogJoinEvent(user.Id, channel.Id, joinTime, user.Id))
+	store.Must(ss.ChannelMemberHistory().LogLeaveEvent(user.Id, channel.Id, leaveTime, user.Id, model.CHANNEL_MEMBER_LEAVE_REASON_LEFT))
 
 	// log a join event
 	leaveTime = model.GetMillis()
 	joinTime = leaveTime - 10000
-	store.Must(ss.ChannelMemberHistory().LogJoinEvent(user.Id, channel.Id, joinTime))
+	store.Must(ss.ChannelMemberHistory().LogJoinEvent(user.Id, channel.Id, joinTime, user.Id))
 
 	// case 1: user joins and leaves the channel before the export period begins
 	channelMembers := store.Must(ss.ChannelMemberHistory().GetUsersInChannelDuring(joinTime-500, joinTime-100, channel.Id)).([]*model.ChannelMemberHistoryResult)
@@ -126,7 +128,7 @@ func testGetUsersInChannelAtChannelMemberHistory(t *testing.T, ss store.Store) {
 	assert.Nil(t, channelMembers[0].LeaveTime)
 
 	// add a leave time for the user
-	store.Must(ss.ChannelMemberHistory().LogLeaveEvent(user.Id, channel.Id, leaveTime))
+	store.Must(ss.ChannelMemberHistory().LogLeaveEvent(user.Id, channel.Id, leaveTime, user.Id, model.CHANNEL_MEMBER_LEAVE_REASON_LEFT))
 
 	// case 4: user joins the channel before the export period begins, but has not yet left the channel when the export period ends
 	channelMembers = store.Must(ss.ChannelMemberHistory().GetUsersInChannelDuring(joinTime+100, leaveTime-100, channel.Id)).([]*model.ChannelMemberHistoryResult)
@@ -283,11 +285,11 @@ func testPermanentDeleteBatch(t *testing.T, ss store.Store) {
 	// user1 joins and leaves the channel
 	leaveTime := model.GetMillis()
 	joinTime := leaveTime - 10000
-	store.Must(ss.ChannelMemberHistory().LogJoinEvent(user.Id, channel.Id, joinTime))
-	store.Must(ss.ChannelMemberHistory().LogLeaveEvent(user.Id, channel.Id, leaveTime))
+	store.Must(ss.ChannelMemberHistory().LogJoinEvent(user.Id, channel.Id, joinTime, user.Id))
+	store.Must(ss.ChannelMemberHistory().LogLeaveEvent(user.Id, channel.Id, leaveTime, user.Id, model.CHANNEL_MEMBER_LEAVE_REASON_LEFT))
 
 	// user2 joins the channel but never leaves
-	store.Must(ss.ChannelMemberHistory().LogJoinEvent(user2.Id, channel.Id, joinTime))
+	store.Must(ss.ChannelMemberHistory().LogJoinEvent(user2.Id, channel.Id, joinTime, user2.Id))
 
 	// in between the join time and the leave time, both users were members of the channel
 	channelMembers := store.Must(ss.ChannelMemberHistory().GetUsersInChannelDuring(joinTime+10, leaveTime-10, channel.Id)).([]*model.ChannelMemberHistoryResult)
@@ -302,3 +304,102 @@ func testPermanentDeleteBatch(t *testing.T, ss store.Store) {
 	assert.Len(t, channelMembers, 1)
 	assert.Equal(t, user2.Id, channelMembers[0].UserId)
 }
+
+func testGetChannelHistory(t *testing.T, ss store.Store) {
+	// create a test channel
+	channel := model.Channel{
+		TeamId:      model.NewId(),
+		DisplayName: "Display " + model.NewId(),
+		Name:        "zz" + model.NewId() + "b",
+		Type:        model.CHANNEL_OPEN,
+	}
+	channel = *store.Must(ss.Channel().Save(&channel, -1)).(*model.Channel)
+
+	// and three test users
+	user := model.User{
+		Email:    model.NewId() + "@mattermost.com",
+		Nickname: model.NewId(),
+		Username: model.NewId(),
+	}
+	user = *store.Must(ss.User().Save(&user)).(*model.User)
+
+	user2 := model.User{
+		Email:    model.NewId() + "@mattermost.com",
+		Nickname: model.NewId(),
+		Username: model.NewId(),
+	}
+	user2 = *store.Must(ss.User().Save(&user2)).(*model.User)
+
+	user3 := model.User{
+		Email:    model.NewId() + "@mattermost.com",
+		Nickname: model.NewId(),
+		Username: model.NewId(),
+	}
+	user3 = *store.Must(ss.User().Save(&user3)).(*model.User)
+
+	// user1 joins the channel and adds user2, who is later removed by user1
+	now := model.GetMillis()
+	store.Must(ss.ChannelMemberHistory().LogJoinEvent(user.Id, channel.Id, now-30000, user.Id))
+	store.Must(ss.ChannelMemberHistory().LogJoinEvent(user2.Id, channel.Id, now-20000, user.Id))
+	store.Must(ss.ChannelMemberHistory().LogLeaveEvent(user2.Id, channel.Id, now-10000, user.Id, model.CHANNEL_MEMBER_LEAVE_REASON_REMOVED))
+
+	// user3 is added by the system and leaves by themselves
+	store.Must(ss.ChannelMemberHistory().LogJoinEvent(user3.Id, channel.Id, now-5000, model.CHANNEL_MEMBER_HISTORY_ACTOR_SYSTEM))
+	store.Must(ss.ChannelMemberHistory().LogLeaveEvent(user3.Id, channel.Id, now, user3.Id, model.CHANNEL_MEMBER_LEAVE_REASON_LEFT))
+
+	// the whole history is returned, most recently joined first
+	histories := store.Must(ss.ChannelMemberHistory().GetChannelHistory(channel.Id, now-30000, now, 0, 10)).([]*model.ChannelMemberHistoryResult)
+	if assert.Len(t, histories, 3) {
+		assert.Equal(t, user3.Id, histories[0].UserId)
+		assert.Equal(t, model.CHANNEL_MEMBER_HISTORY_ACTOR_SYSTEM, histories[0].JoinActorId)
+		assert.Equal(t, user3.Id, histories[0].LeaveActorId)
+		assert.Equal(t, model.CHANNEL_MEMBER_LEAVE_REASON_LEFT, histories[0].LeaveReason)
+
+		assert.Equal(t, user2.Id, histories[1].UserId)
+		assert.Equal(t, user2.Username, histories[1].Username)
+		assert.Equal(t, user.Id, histories[1].JoinActorId)
+		assert.Equal(t, user.Id, histories[1].LeaveActorId)
+		assert.Equal(t, model.CHANNEL_MEMBER_LEAVE_REASON_REMOVED, histories[1].LeaveReason)
+
+		assert.Equal(t, user.Id, histories[2].UserId)
+		assert.Nil(t, histories[2].LeaveTime)
+		assert.Equal(t, "", histories[2].LeaveReason)
+	}
+
+	// a membership is returned if either its start or its end is in the period
+	histories = store.Must(ss.ChannelMemberHistory().GetChannelHistory(channel.Id, now-15000, now-6000, 0, 10)).([]*model.ChannelMemberHistoryResult)
+	if assert.Len(t, histories, 1) {
+		assert.Equal(t, user2.Id, histories[0].UserId)
+	}
+
+	// the history is paged
+	histories = store.Must(ss.ChannelMemberHistory().GetChannelHistory(channel.Id, now-30000, now, 1, 1)).([]*model.ChannelMemberHistoryResult)
+	if assert.Len(t, histories, 1) {
+		assert.Equal(t, user2.Id, histories[0].UserId)
+	}
+
+	histories = store.Must(ss.ChannelMemberHistory().GetChannelHistory(channel.Id, now-30000, now, 3, 10)).([]*model.ChannelMemberHistoryResult)
+	assert.Len(t, histories, 0)
+}
+
+func testGetLastLeaveTime(t *testing.T, ss store.Store) {
+	channelId := model.NewId()
+	userId := model.NewId()
+
+	// a user who never joined the channel hasn't left it
+	assert.Equal(t, int64(0), store.Must(ss.ChannelMemberHistory().GetLastLeaveTime(userId, channelId)).(int64))
+
+	// nor has one who's still in it
+	now := model.GetMillis()
+	store.Must(ss.ChannelMemberHistory().LogJoinEvent(userId, channelId, now-30000, userId))
+	assert.Equal(t, int64(0), store.Must(ss.ChannelMemberHistory().GetLastLeaveTime(userId, channelId)).(int64))
+
+	store.Must(ss.ChannelMemberHistory().LogLeaveEvent(userId, channelId, now-20000, userId, model.CHANNEL_MEMBER_LEAVE_REASON_LEFT))
+	assert.Equal(t, now-20000, store.Must(ss.ChannelMemberHistory().GetLastLeaveTime(userId, channelId)).(int64))
+
+	// the latest of several memberships is used
+	store.Must(ss.ChannelMemberHistory().LogJoinEvent(userId, channelId, now-10000, userId))
+	store.Must(ss.ChannelMemberHistory().LogLeaveEvent(userId, channelId, now, userId, model.CHANNEL_MEMBER_LEAVE_REASON_REMOVED))
+	assert.Equal(t, now, store.Must(ss.ChannelMemberHistory().GetLastLeaveTime(userId, channelId)).(int64))
+	assert.Equal(t, int64(0), store.Must(ss.ChannelMemberHistory().GetLastLeaveTime(model.NewId(), channelId)).(int64))
+}
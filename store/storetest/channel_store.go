@@ -4,6 +4,7 @@
 package storetest
 
 import (
+	"net/http"
 	"sort"
 	"testing"
 	"time"
@@ -21,9 +22,12 @@ func TestChannelStore(t *testing.T, ss store.Store) {
 	t.Run("CreateDirectChannel", func(t *testing.T) { testChannelStoreCreateDirectChannel(t, ss) })
 	t.Run("Update", func(t *testing.T) { testChannelStoreUpdate(t, ss) })
 	t.Run("GetChannelUnread", func(t *testing.T) { testGetChannelUnread(t, ss) })
+	t.Run("GetChannelUnreadsForUser", func(t *testing.T) { testGetChannelUnreadsForUser(t, ss) })
 	t.Run("Get", func(t *testing.T) { testChannelStoreGet(t, ss) })
 	t.Run("GetForPost", func(t *testing.T) { testChannelStoreGetForPost(t, ss) })
+	t.Run("GetByIconEmojiName", func(t *testing.T) { testChannelStoreGetByIconEmojiName(t, ss) })
 	t.Run("Restore", func(t *testing.T) { testChannelStoreRestore(t, ss) })
+	t.Run("ArchiveDirectChannelsForUser", func(t *testing.T) { testChannelStoreArchiveDirectChannelsForUser(t, ss) })
 	t.Run("Delete", func(t *testing.T) { testChannelStoreDelete(t, ss) })
 	t.Run("GetByName", func(t *testing.T) { testChannelStoreGetByName(t, ss) })
 	t.Run("GetByNames", func(t *testing.T) { testChannelStoreGetByNames(t, ss) })
@@ -38,17 +42,36 @@ func TestChannelStore(t *testing.T, ss store.Store) {
 	t.Run("GetChannelCounts", func(t *testing.T) { testChannelStoreGetChannelCounts(t, ss) })
 	t.Run("GetMembersForUser", func(t *testing.T) { testChannelStoreGetMembersForUser(t, ss) })
 	t.Run("UpdateLastViewedAt", func(t *testing.T) { testChannelStoreUpdateLastViewedAt(t, ss) })
+	t.Run("UpdateViewedAt", func(t *testing.T) { testChannelStoreUpdateViewedAt(t, ss) })
 	t.Run("IncrementMentionCount", func(t *testing.T) { testChannelStoreIncrementMentionCount(t, ss) })
+	t.Run("UpdateLastViewedAtPost", func(t *testing.T) { testChannelStoreUpdateLastViewedAtPost(t, ss) })
+	t.Run("UpdateLastReadPost", func(t *testing.T) { testChannelStoreUpdateLastReadPost(t, ss) })
+	t.Run("UpdateLastReadPostsToLatest", func(t *testing.T) { testChannelStoreUpdateLastReadPostsToLatest(t, ss) })
+	t.Run("GetMembersWithUnreadMentions", func(t *testing.T) { testChannelStoreGetMembersWithUnreadMentions(t, ss) })
+	t.Run("RemovePostsFromCounts", func(t *testing.T) { testChannelStoreRemovePostsFromCounts(t, ss) })
+	t.Run("IncrementMsgCountForUsersWithPreference", func(t *testing.T) { testChannelStoreIncrementMsgCountForUsersWithPreference(t, ss) })
 	t.Run("UpdateChannelMember", func(t *testing.T) { testUpdateChannelMember(t, ss) })
+	t.Run("UpdateChannelMembers", func(t *testing.T) { testUpdateChannelMembers(t, ss) })
 	t.Run("GetMember", func(t *testing.T) { testGetMember(t, ss) })
 	t.Run("GetMemberForPost", func(t *testing.T) { testChannelStoreGetMemberForPost(t, ss) })
 	t.Run("GetMemberCount", func(t *testing.T) { testGetMemberCount(t, ss) })
+	t.Run("MemberCounts", func(t *testing.T) { testChannelStoreMemberCounts(t, ss) })
+	t.Run("MemberCountsConcurrentJoins", func(t *testing.T) { testChannelStoreMemberCountsConcurrentJoins(t, ss) })
 	t.Run("SearchMore", func(t *testing.T) { testChannelStoreSearchMore(t, ss) })
+	t.Run("SearchForUserInTeam", func(t *testing.T) { testChannelStoreSearchForUserInTeam(t, ss) })
 	t.Run("SearchInTeam", func(t *testing.T) { testChannelStoreSearchInTeam(t, ss) })
 	t.Run("GetMembersByIds", func(t *testing.T) { testChannelStoreGetMembersByIds(t, ss) })
 	t.Run("AnalyticsDeletedTypeCount", func(t *testing.T) { testChannelStoreAnalyticsDeletedTypeCount(t, ss) })
+	t.Run("AnalyticsFeedCount", func(t *testing.T) { testChannelStoreAnalyticsFeedCount(t, ss) })
 	t.Run("GetPinnedPosts", func(t *testing.T) { testChannelStoreGetPinnedPosts(t, ss) })
+	t.Run("GetInactivePublicChannels", func(t *testing.T) { testChannelStoreGetInactivePublicChannels(t, ss) })
+	t.Run("PermanentDeletion", func(t *testing.T) { testChannelStorePermanentDeletion(t, ss) })
+	t.Run("GetBatchForTeam", func(t *testing.T) { testChannelStoreGetBatchForTeam(t, ss) })
 	t.Run("MaxChannelsPerTeam", func(t *testing.T) { testChannelStoreMaxChannelsPerTeam(t, ss) })
+	t.Run("CreateInitialSidebarCategories", func(t *testing.T) { testChannelStoreCreateInitialSidebarCategories(t, ss) })
+	t.Run("SidebarCategories", func(t *testing.T) { testChannelStoreSidebarCategories(t, ss) })
+	t.Run("ModeratedPermissions", func(t *testing.T) { testChannelStoreModeratedPermissions(t, ss) })
+	t.Run("NameHistory", func(t *testing.T) { testChannelStoreNameHistory(t, ss) })
 }
 
 func testChannelStoreSave(t *testing.T, ss store.Store) {
@@ -300,6 +323,55 @@ func testGetChannelUnread(t *testing.T, ss store.Store) {
 	}
 }
 
+func testGetChannelUnreadsForUser(t *testing.T, ss store.Store) {
+	uid := model.NewId()
+	teamId1 := model.NewId()
+	teamId2 := model.NewId()
+	notifyProps := model.GetDefaultChannelNotifyProps()
+
+	c1 := &model.Channel{TeamId: teamId1, Name: model.NewId(), DisplayName: "Downtown", Type: model.CHANNEL_OPEN, TotalMsgCount: 100}
+	store.Must(ss.Channel().Save(c1, -1))
+	store.Must(ss.Channel().SaveMember(&model.ChannelMember{ChannelId: c1.Id, UserId: uid, NotifyProps: notifyProps, MsgCount: 90, MentionCount: 2, LastViewedAt: 1234}))
+
+	c2 := &model.Channel{TeamId: teamId2, Name: model.NewId(), DisplayName: "Cultural", Type: model.CHANNEL_OPEN, TotalMsgCount: 10}
+	store.Must(ss.Channel().Save(c2, -1))
+	store.Must(ss.Channel().SaveMember(&model.ChannelMember{ChannelId: c2.Id, UserId: uid, NotifyProps: notifyProps, MsgCount: 10}))
+
+	c3 := &model.Channel{TeamId: "", Name: model.NewId(), DisplayName: "Group", Type: model.CHANNEL_GROUP, TotalMsgCount: 3}
+	store.Must(ss.Channel().Save(c3, -1))
+	store.Must(ss.Channel().SaveMember(&model.ChannelMember{ChannelId: c3.Id, UserId: uid, NotifyProps: notifyProps, MentionCount: 3}))
+
+	deleted := &model.Channel{TeamId: teamId1, Name: model.NewId(), DisplayName: "Deleted", Type: model.CHANNEL_OPEN, TotalMsgCount: 5}
+	store.Must(ss.Channel().Save(deleted, -1))
+	store.Must(ss.Channel().SaveMember(&model.ChannelMember{ChannelId: deleted.Id, UserId: uid, NotifyProps: notifyProps}))
+	store.Must(ss.Channel().Delete(deleted.Id, model.GetMillis()))
+
+	byChannel := func(unreads []*model.ChannelUnread) map[string]*model.ChannelUnread {
+		m := make(map[string]*model.ChannelUnread)
+		for _, unread := range unreads {
+			m[unread.ChannelId] = unread
+		}
+		return m
+	}
+
+	unreads := byChannel(store.Must(ss.Channel().GetChannelUnreadsForUser(teamId1, uid)).([]*model.ChannelUnread))
+	require.Len(t, unreads, 2, "should include the team's channels and group messages but not deleted channels")
+	assert.Equal(t, int64(10), unreads[c1.Id].MsgCount)
+	assert.Equal(t, int64(2), unreads[c1.Id].MentionCount)
+	assert.Equal(t, int64(1234), unreads[c1.Id].LastViewedAt)
+	assert.Equal(t, teamId1, unreads[c1.Id].TeamId)
+	assert.NotNil(t, unreads[c1.Id].NotifyProps)
+	assert.Equal(t, int64(3), unreads[c3.Id].MsgCount)
+	assert.Equal(t, int64(3), unreads[c3.Id].MentionCount)
+
+	unreads = byChannel(store.Must(ss.Channel().GetChannelUnreadsForUser("", uid)).([]*model.ChannelUnread))
+	require.Len(t, unreads, 3)
+	assert.Equal(t, int64(0), unreads[c2.Id].MsgCount)
+	assert.Equal(t, teamId2, unreads[c2.Id].TeamId)
+
+	assert.Len(t, store.Must(ss.Channel().GetChannelUnreadsForUser(teamId1, model.NewId())).([]*model.ChannelUnread), 0)
+}
+
 func testChannelStoreGet(t *testing.T, ss store.Store) {
 	o1 := model.Channel{}
 	o1.TeamId = model.NewId()
@@ -432,6 +504,60 @@ func testChannelStoreRestore(t *testing.T, ss store.Store) {
 
 }
 
+func testChannelStoreArchiveDirectChannelsForUser(t *testing.T, ss store.Store) {
+	u1 := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Nickname: model.NewId()})).(*model.User)
+	u2 := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Nickname: model.NewId()})).(*model.User)
+	u3 := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Nickname: model.NewId()})).(*model.User)
+
+	dm1 := store.Must(ss.Channel().CreateDirectChannel(u1.Id, u2.Id)).(*model.Channel)
+	dm2 := store.Must(ss.Channel().CreateDirectChannel(u1.Id, u3.Id)).(*model.Channel)
+
+	o1 := model.Channel{}
+	o1.TeamId = model.NewId()
+	o1.DisplayName = "Channel1"
+	o1.Name = "zz" + model.NewId() + "b"
+	o1.Type = model.CHANNEL_OPEN
+	store.Must(ss.Channel().Save(&o1, -1))
+	store.Must(ss.Channel().SaveMember(&model.ChannelMember{ChannelId: o1.Id, UserId: u1.Id, NotifyProps: model.GetDefaultChannelNotifyProps()}))
+
+	result := <-ss.Channel().ArchiveDirectChannelsForUser(u1.Id, model.GetMillis())
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]*model.Channel), 2)
+
+	for _, channelId := range []string{dm1.Id, dm2.Id} {
+		channel := store.Must(ss.Channel().Get(channelId, false)).(*model.Channel)
+		assert.NotZero(t, channel.DeleteAt, "direct channels should've been archived")
+	}
+	assert.Zero(t, store.Must(ss.Channel().Get(o1.Id, false)).(*model.Channel).DeleteAt, "other channels shouldn't be archived")
+
+	// Archiving again doesn't change anything
+	result = <-ss.Channel().ArchiveDirectChannelsForUser(u1.Id, model.GetMillis())
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]*model.Channel), 0)
+
+	// Direct channels with users who are still deactivated stay archived
+	u3.DeleteAt = model.GetMillis()
+	store.Must(ss.User().Update(u3, true))
+
+	result = <-ss.Channel().RestoreDirectChannelsForUser(u1.Id, model.GetMillis())
+	require.Nil(t, result.Err)
+	restored := result.Data.([]*model.Channel)
+	require.Len(t, restored, 1)
+	assert.Equal(t, dm1.Id, restored[0].Id)
+	assert.Zero(t, restored[0].DeleteAt)
+
+	assert.Zero(t, store.Must(ss.Channel().Get(dm1.Id, false)).(*model.Channel).DeleteAt)
+	assert.NotZero(t, store.Must(ss.Channel().Get(dm2.Id, false)).(*model.Channel).DeleteAt)
+
+	u3.DeleteAt = 0
+	store.Must(ss.User().Update(u3, true))
+
+	result = <-ss.Channel().RestoreDirectChannelsForUser(u3.Id, model.GetMillis())
+	require.Nil(t, result.Err)
+	require.Len(t, result.Data.([]*model.Channel), 1)
+	assert.Zero(t, store.Must(ss.Channel().Get(dm2.Id, false)).(*model.Channel).DeleteAt)
+}
+
 func testChannelStoreDelete(t *testing.T, ss store.Store) {
 	o1 := model.Channel{}
 	o1.TeamId = model.NewId()
@@ -1349,6 +1475,235 @@ func testChannelStoreUpdateLastViewedAt(t *testing.T, ss store.Store) {
 	}
 }
 
+func testChannelStoreUpdateViewedAt(t *testing.T, ss store.Store) {
+	channel := store.Must(ss.Channel().Save(&model.Channel{
+		TeamId:        model.NewId(),
+		DisplayName:   "Name",
+		Name:          "zz" + model.NewId() + "b",
+		Type:          model.CHANNEL_OPEN,
+		TotalMsgCount: 10,
+		LastPostAt:    1000,
+	}, -1)).(*model.Channel)
+
+	userId := model.NewId()
+	store.Must(ss.Channel().SaveMember(&model.ChannelMember{ChannelId: channel.Id, UserId: userId, NotifyProps: model.GetDefaultChannelNotifyProps()}))
+	store.Must(ss.Channel().IncrementMentionCount(channel.Id, userId))
+
+	result := <-ss.Channel().GetViewedAt([]string{channel.Id, model.NewId()})
+	require.Nil(t, result.Err)
+	views := result.Data.([]*model.ChannelViewedAt)
+	require.Len(t, views, 1)
+	assert.Equal(t, &model.ChannelViewedAt{ChannelId: channel.Id, LastPostAt: 1000, TotalMsgCount: 10}, views[0])
+
+	// Getting the views doesn't change the member
+	member := store.Must(ss.Channel().GetMember(channel.Id, userId)).(*model.ChannelMember)
+	assert.Equal(t, int64(0), member.LastViewedAt)
+	assert.Equal(t, int64(1), member.MentionCount)
+
+	require.Nil(t, (<-ss.Channel().UpdateViewedAt(userId, views)).Err)
+
+	member = store.Must(ss.Channel().GetMember(channel.Id, userId)).(*model.ChannelMember)
+	assert.Equal(t, int64(1000), member.LastViewedAt)
+	assert.Equal(t, int64(10), member.MsgCount)
+	assert.Equal(t, int64(0), member.MentionCount)
+
+	// An older view doesn't move the member's read position back
+	require.Nil(t, (<-ss.Channel().UpdateViewedAt(userId, []*model.ChannelViewedAt{{ChannelId: channel.Id, LastPostAt: 500, TotalMsgCount: 5}})).Err)
+
+	member = store.Must(ss.Channel().GetMember(channel.Id, userId)).(*model.ChannelMember)
+	assert.Equal(t, int64(1000), member.LastViewedAt)
+	assert.Equal(t, int64(10), member.MsgCount)
+
+	require.Nil(t, (<-ss.Channel().UpdateViewedAt(userId, nil)).Err)
+}
+
+func testChannelStoreUpdateLastViewedAtPost(t *testing.T, ss store.Store) {
+	channel := store.Must(ss.Channel().Save(&model.Channel{TeamId: model.NewId(), DisplayName: "Name", Name: "zz" + model.NewId() + "b", Type: model.CHANNEL_OPEN}, -1)).(*model.Channel)
+
+	userId := model.NewId()
+	store.Must(ss.Channel().SaveMember(&model.ChannelMember{ChannelId: channel.Id, UserId: userId, NotifyProps: model.GetDefaultChannelNotifyProps()}))
+
+	start := model.GetMillis()
+	save := func(createAt int64, postType string) *model.Post {
+		return store.Must(ss.Post().Save(&model.Post{ChannelId: channel.Id, UserId: model.NewId(), Message: "message", Type: postType, CreateAt: createAt})).(*model.Post)
+	}
+
+	first := save(start, "")
+	second := save(start+1, "")
+	save(start+2, model.POST_JOIN_CHANNEL)
+	third := save(start+3, "")
+
+	// editing a post leaves its previous version behind, which shouldn't count as a message
+	edited := &model.Post{}
+	*edited = *third
+	edited.Message = "edited"
+	store.Must(ss.Post().Update(edited, third))
+
+	store.Must(ss.Channel().UpdateLastViewedAt([]string{channel.Id}, userId))
+
+	unread := store.Must(ss.Channel().UpdateLastViewedAtPost(second, userId, 1)).(*model.ChannelUnread)
+	assert.Equal(t, channel.Id, unread.ChannelId)
+	assert.Equal(t, int64(2), unread.MsgCount)
+	assert.Equal(t, int64(1), unread.MentionCount)
+	assert.Equal(t, second.CreateAt-1, unread.LastViewedAt)
+	assert.Equal(t, first.Id, unread.LastReadPostId)
+
+	member := store.Must(ss.Channel().GetMember(channel.Id, userId)).(*model.ChannelMember)
+	assert.Equal(t, int64(1), member.MsgCount)
+	assert.Equal(t, int64(1), member.MentionCount)
+	assert.Equal(t, second.CreateAt-1, member.LastViewedAt)
+	assert.Equal(t, first.Id, member.LastReadPostId)
+
+	// marking the first post in the channel as unread leaves every message unread
+	unread = store.Must(ss.Channel().UpdateLastViewedAtPost(first, userId, 0)).(*model.ChannelUnread)
+	assert.Equal(t, int64(3), unread.MsgCount)
+	assert.Equal(t, int64(0), unread.MentionCount)
+	assert.Equal(t, "", unread.LastReadPostId)
+
+	// deleted posts are no longer counted as unread
+	store.Must(ss.Post().Delete(second.Id, model.GetMillis()))
+	store.Must(ss.Channel().RemovePostsFromCounts(channel.Id, []*model.Post{second}, nil))
+	unread = store.Must(ss.Channel().GetChannelUnread(channel.Id, userId)).(*model.ChannelUnread)
+	assert.Equal(t, int64(2), unread.MsgCount)
+
+	unread = store.Must(ss.Channel().UpdateLastViewedAtPost(second, userId, 0)).(*model.ChannelUnread)
+	assert.Equal(t, int64(1), unread.MsgCount)
+
+	result := <-ss.Channel().UpdateLastViewedAtPost(second, model.NewId(), 0)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+}
+
+func testChannelStoreUpdateLastReadPost(t *testing.T, ss store.Store) {
+	channel := store.Must(ss.Channel().Save(&model.Channel{TeamId: model.NewId(), DisplayName: "Name", Name: "zz" + model.NewId() + "b", Type: model.CHANNEL_OPEN}, -1)).(*model.Channel)
+
+	userId := model.NewId()
+	store.Must(ss.Channel().SaveMember(&model.ChannelMember{ChannelId: channel.Id, UserId: userId, NotifyProps: model.GetDefaultChannelNotifyProps()}))
+
+	start := model.GetMillis()
+	first := store.Must(ss.Post().Save(&model.Post{ChannelId: channel.Id, UserId: model.NewId(), Message: "message", CreateAt: start})).(*model.Post)
+	second := store.Must(ss.Post().Save(&model.Post{ChannelId: channel.Id, UserId: model.NewId(), Message: "message", CreateAt: start + 1})).(*model.Post)
+
+	unread := store.Must(ss.Channel().UpdateLastReadPost(second, userId)).(*model.ChannelUnread)
+	assert.Equal(t, channel.Id, unread.ChannelId)
+	assert.Equal(t, second.Id, unread.LastReadPostId)
+
+	// reading an earlier post doesn't move the last read post backwards
+	unread = store.Must(ss.Channel().UpdateLastReadPost(first, userId)).(*model.ChannelUnread)
+	assert.Equal(t, second.Id, unread.LastReadPostId)
+
+	member := store.Must(ss.Channel().GetMember(channel.Id, userId)).(*model.ChannelMember)
+	assert.Equal(t, second.Id, member.LastReadPostId)
+	assert.Equal(t, second.CreateAt, member.LastReadPostAt)
+
+	unread = store.Must(ss.Channel().GetChannelUnread(channel.Id, userId)).(*model.ChannelUnread)
+	assert.Equal(t, second.Id, unread.LastReadPostId)
+
+	result := <-ss.Channel().UpdateLastReadPost(second, model.NewId())
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+}
+
+func testChannelStoreUpdateLastReadPostsToLatest(t *testing.T, ss store.Store) {
+	channel := store.Must(ss.Channel().Save(&model.Channel{TeamId: model.NewId(), DisplayName: "Name", Name: "zz" + model.NewId() + "b", Type: model.CHANNEL_OPEN}, -1)).(*model.Channel)
+	empty := store.Must(ss.Channel().Save(&model.Channel{TeamId: model.NewId(), DisplayName: "Name", Name: "zz" + model.NewId() + "b", Type: model.CHANNEL_OPEN}, -1)).(*model.Channel)
+
+	userId := model.NewId()
+	store.Must(ss.Channel().SaveMember(&model.ChannelMember{ChannelId: channel.Id, UserId: userId, NotifyProps: model.GetDefaultChannelNotifyProps()}))
+	store.Must(ss.Channel().SaveMember(&model.ChannelMember{ChannelId: empty.Id, UserId: userId, NotifyProps: model.GetDefaultChannelNotifyProps()}))
+
+	start := model.GetMillis()
+	first := store.Must(ss.Post().Save(&model.Post{ChannelId: channel.Id, UserId: model.NewId(), Message: "message", CreateAt: start})).(*model.Post)
+	second := store.Must(ss.Post().Save(&model.Post{ChannelId: channel.Id, UserId: model.NewId(), Message: "message", CreateAt: start + 1})).(*model.Post)
+
+	// deleted posts can't be the last read post
+	deleted := store.Must(ss.Post().Save(&model.Post{ChannelId: channel.Id, UserId: model.NewId(), Message: "message", CreateAt: start + 2})).(*model.Post)
+	store.Must(ss.Post().Delete(deleted.Id, model.GetMillis()))
+
+	store.Must(ss.Channel().UpdateLastReadPost(first, userId))
+
+	lastReadPostIds := store.Must(ss.Channel().UpdateLastReadPostsToLatest([]string{channel.Id, empty.Id}, userId)).(map[string]string)
+	assert.Equal(t, map[string]string{channel.Id: second.Id, empty.Id: ""}, lastReadPostIds)
+
+	member := store.Must(ss.Channel().GetMember(channel.Id, userId)).(*model.ChannelMember)
+	assert.Equal(t, second.Id, member.LastReadPostId)
+	assert.Equal(t, second.CreateAt, member.LastReadPostAt)
+}
+
+func testChannelStoreGetMembersWithUnreadMentions(t *testing.T, ss store.Store) {
+	channel := store.Must(ss.Channel().Save(&model.Channel{TeamId: model.NewId(), DisplayName: "Name", Name: "zz" + model.NewId() + "b", Type: model.CHANNEL_OPEN}, -1)).(*model.Channel)
+
+	saveMember := func(lastViewedAt int64, mentionCount int64) *model.ChannelMember {
+		member := store.Must(ss.Channel().SaveMember(&model.ChannelMember{ChannelId: channel.Id, UserId: model.NewId(), NotifyProps: model.GetDefaultChannelNotifyProps()})).(*model.ChannelMember)
+		member.LastViewedAt = lastViewedAt
+		member.MentionCount = mentionCount
+		return store.Must(ss.Channel().UpdateMember(member)).(*model.ChannelMember)
+	}
+
+	early := saveMember(100, 1)
+	saveMember(300, 2)
+	saveMember(100, 0)
+
+	members := *store.Must(ss.Channel().GetMembersWithUnreadMentions(channel.Id, 200)).(*model.ChannelMembers)
+	require.Len(t, members, 1)
+	assert.Equal(t, early.UserId, members[0].UserId)
+
+	members = *store.Must(ss.Channel().GetMembersWithUnreadMentions(channel.Id, 400)).(*model.ChannelMembers)
+	assert.Len(t, members, 2)
+
+	members = *store.Must(ss.Channel().GetMembersWithUnreadMentions(channel.Id, 100)).(*model.ChannelMembers)
+	assert.Len(t, members, 0)
+
+	members = *store.Must(ss.Channel().GetMembersWithUnreadMentions(model.NewId(), 400)).(*model.ChannelMembers)
+	assert.Len(t, members, 0)
+}
+
+func testChannelStoreRemovePostsFromCounts(t *testing.T, ss store.Store) {
+	channel := store.Must(ss.Channel().Save(&model.Channel{TeamId: model.NewId(), DisplayName: "Name", Name: "zz" + model.NewId() + "b", Type: model.CHANNEL_OPEN}, -1)).(*model.Channel)
+
+	save := func(createAt int64, postType string) *model.Post {
+		return store.Must(ss.Post().Save(&model.Post{ChannelId: channel.Id, UserId: model.NewId(), Message: "message", Type: postType, CreateAt: createAt})).(*model.Post)
+	}
+
+	first := save(100, "")
+	save(200, "")
+	joined := save(250, model.POST_JOIN_CHANNEL)
+	third := save(300, "")
+
+	saveMember := func(lastViewedAt int64, msgCount int64, mentionCount int64) string {
+		member := store.Must(ss.Channel().SaveMember(&model.ChannelMember{ChannelId: channel.Id, UserId: model.NewId(), NotifyProps: model.GetDefaultChannelNotifyProps()})).(*model.ChannelMember)
+		member.LastViewedAt = lastViewedAt
+		member.MsgCount = msgCount
+		member.MentionCount = mentionCount
+		store.Must(ss.Channel().UpdateMember(member))
+		return member.UserId
+	}
+
+	// the members have read none, some and all of the posts
+	unread := saveMember(0, 0, 2)
+	partlyRead := saveMember(250, 2, 1)
+	read := saveMember(300, 3, 0)
+
+	store.Must(ss.Channel().RemovePostsFromCounts(channel.Id, []*model.Post{first, joined, third}, map[string]int64{unread: 1, partlyRead: 3}))
+
+	assertUnread := func(userId string, msgCount int64, mentionCount int64) {
+		channelUnread := store.Must(ss.Channel().GetChannelUnread(channel.Id, userId)).(*model.ChannelUnread)
+		assert.Equal(t, msgCount, channelUnread.MsgCount, "wrong unread messages")
+		assert.Equal(t, mentionCount, channelUnread.MentionCount, "wrong mentions")
+	}
+
+	assertUnread(unread, 1, 1)
+	assertUnread(partlyRead, 0, 0)
+	assertUnread(read, 0, 0)
+
+	updated := store.Must(ss.Channel().Get(channel.Id, false)).(*model.Channel)
+	assert.Equal(t, int64(1), updated.TotalMsgCount)
+
+	// posts that aren't counted change nothing
+	store.Must(ss.Channel().RemovePostsFromCounts(channel.Id, []*model.Post{joined}, nil))
+	assertUnread(unread, 1, 1)
+}
+
 func testChannelStoreIncrementMentionCount(t *testing.T, ss store.Store) {
 	o1 := model.Channel{}
 	o1.TeamId = model.NewId()
@@ -1385,6 +1740,53 @@ func testChannelStoreIncrementMentionCount(t *testing.T, ss store.Store) {
 	}
 }
 
+func testChannelStoreIncrementMsgCountForUsersWithPreference(t *testing.T, ss store.Store) {
+	o1 := model.Channel{}
+	o1.TeamId = model.NewId()
+	o1.DisplayName = "Channel1"
+	o1.Name = "zz" + model.NewId() + "b"
+	o1.Type = model.CHANNEL_OPEN
+	o1.TotalMsgCount = 25
+	store.Must(ss.Channel().Save(&o1, -1))
+
+	m1 := model.ChannelMember{}
+	m1.ChannelId = o1.Id
+	m1.UserId = model.NewId()
+	m1.MsgCount = 20
+	m1.NotifyProps = model.GetDefaultChannelNotifyProps()
+	store.Must(ss.Channel().SaveMember(&m1))
+
+	m2 := model.ChannelMember{}
+	m2.ChannelId = o1.Id
+	m2.UserId = model.NewId()
+	m2.MsgCount = 20
+	m2.NotifyProps = model.GetDefaultChannelNotifyProps()
+	store.Must(ss.Channel().SaveMember(&m2))
+
+	category := model.PREFERENCE_CATEGORY_ADVANCED_SETTINGS
+	name := model.NewId()
+	store.Must(ss.Preference().Save(&model.Preferences{
+		{UserId: m1.UserId, Category: category, Name: name, Value: "false"},
+		{UserId: m2.UserId, Category: category, Name: name, Value: "true"},
+	}))
+
+	if err := (<-ss.Channel().IncrementMsgCountForUsersWithPreference(o1.Id, category, name, "false")).Err; err != nil {
+		t.Fatal(err)
+	}
+
+	if member := store.Must(ss.Channel().GetMember(o1.Id, m1.UserId)).(*model.ChannelMember); member.MsgCount != 21 {
+		t.Fatal("should've incremented the message count for the member with the preference")
+	}
+
+	if member := store.Must(ss.Channel().GetMember(o1.Id, m2.UserId)).(*model.ChannelMember); member.MsgCount != 20 {
+		t.Fatal("shouldn't have incremented the message count for the member without the preference")
+	}
+
+	if err := (<-ss.Channel().IncrementMsgCountForUsersWithPreference("missing id", category, name, "false")).Err; err != nil {
+		t.Fatal(err)
+	}
+}
+
 func testUpdateChannelMember(t *testing.T, ss store.Store) {
 	userId := model.NewId()
 
@@ -1414,6 +1816,52 @@ func testUpdateChannelMember(t *testing.T, ss store.Store) {
 	}
 }
 
+func testUpdateChannelMembers(t *testing.T, ss store.Store) {
+	channel := store.Must(ss.Channel().Save(&model.Channel{
+		TeamId:      model.NewId(),
+		DisplayName: model.NewId(),
+		Name:        model.NewId(),
+		Type:        model.CHANNEL_OPEN,
+	}, -1)).(*model.Channel)
+
+	var members []*model.ChannelMember
+	for i := 0; i < 2; i++ {
+		member := &model.ChannelMember{
+			ChannelId:   channel.Id,
+			UserId:      model.NewId(),
+			Roles:       model.CHANNEL_USER_ROLE_ID,
+			NotifyProps: model.GetDefaultChannelNotifyProps(),
+		}
+		store.Must(ss.Channel().SaveMember(member))
+		members = append(members, member)
+	}
+
+	for _, member := range members {
+		member.Roles = model.CHANNEL_USER_ROLE_ID + " " + model.CHANNEL_ADMIN_ROLE_ID
+	}
+	store.Must(ss.Channel().UpdateMembers(members))
+
+	for _, member := range members {
+		saved := store.Must(ss.Channel().GetMember(channel.Id, member.UserId)).(*model.ChannelMember)
+		assert.Equal(t, model.CHANNEL_USER_ROLE_ID+" "+model.CHANNEL_ADMIN_ROLE_ID, saved.Roles)
+	}
+
+	// None of the members should be updated if one of them can't be
+	members[0].Roles = model.CHANNEL_USER_ROLE_ID
+	missing := &model.ChannelMember{
+		ChannelId:   channel.Id,
+		UserId:      model.NewId(),
+		Roles:       model.CHANNEL_USER_ROLE_ID,
+		NotifyProps: model.GetDefaultChannelNotifyProps(),
+	}
+	result := <-ss.Channel().UpdateMembers([]*model.ChannelMember{members[0], missing})
+	require.NotNil(t, result.Err)
+	assert.Equal(t, store.MISSING_CHANNEL_MEMBER_ERROR, result.Err.Id)
+
+	saved := store.Must(ss.Channel().GetMember(channel.Id, members[0].UserId)).(*model.ChannelMember)
+	assert.Equal(t, model.CHANNEL_USER_ROLE_ID+" "+model.CHANNEL_ADMIN_ROLE_ID, saved.Roles)
+}
+
 func testGetMember(t *testing.T, ss store.Store) {
 	userId := model.NewId()
 
@@ -1625,6 +2073,46 @@ func testGetMemberCount(t *testing.T, ss store.Store) {
 	}
 }
 
+func testChannelStoreSearchForUserInTeam(t *testing.T, ss store.Store) {
+	teamId := model.NewId()
+	userId := model.NewId()
+
+	o1 := model.Channel{TeamId: teamId, DisplayName: "ChannelA", Name: "zz" + model.NewId() + "b", Type: model.CHANNEL_OPEN}
+	store.Must(ss.Channel().Save(&o1, -1))
+
+	o2 := model.Channel{TeamId: teamId, DisplayName: "ChannelA private", Name: "zz" + model.NewId() + "b", Type: model.CHANNEL_PRIVATE}
+	store.Must(ss.Channel().Save(&o2, -1))
+
+	o3 := model.Channel{TeamId: teamId, DisplayName: "ChannelA not a member", Name: "zz" + model.NewId() + "b", Type: model.CHANNEL_OPEN}
+	store.Must(ss.Channel().Save(&o3, -1))
+
+	o4 := model.Channel{TeamId: model.NewId(), DisplayName: "ChannelA other team", Name: "zz" + model.NewId() + "b", Type: model.CHANNEL_OPEN}
+	store.Must(ss.Channel().Save(&o4, -1))
+
+	for _, channel := range []model.Channel{o1, o2, o4} {
+		store.Must(ss.Channel().SaveMember(&model.ChannelMember{ChannelId: channel.Id, UserId: userId, NotifyProps: model.GetDefaultChannelNotifyProps()}))
+	}
+
+	result := <-ss.Channel().SearchForUserInTeam(userId, teamId, "ChannelA")
+	assert.Nil(t, result.Err)
+	channels := result.Data.(*model.ChannelList)
+	assert.Len(t, *channels, 2)
+	for _, channel := range *channels {
+		assert.Contains(t, []string{o1.Id, o2.Id}, channel.Id)
+	}
+
+	result = <-ss.Channel().SearchForUserInTeam(userId, teamId, "private")
+	assert.Nil(t, result.Err)
+	channels = result.Data.(*model.ChannelList)
+	if assert.Len(t, *channels, 1) {
+		assert.Equal(t, o2.Id, (*channels)[0].Id)
+	}
+
+	result = <-ss.Channel().SearchForUserInTeam(model.NewId(), teamId, "ChannelA")
+	assert.Nil(t, result.Err)
+	assert.Len(t, *result.Data.(*model.ChannelList), 0)
+}
+
 func testChannelStoreSearchMore(t *testing.T, ss store.Store) {
 	o1 := model.Channel{}
 	o1.TeamId = model.NewId()
@@ -2025,6 +2513,39 @@ func testChannelStoreGetMembersByIds(t *testing.T, ss store.Store) {
 	}
 }
 
+func testChannelStoreAnalyticsFeedCount(t *testing.T, ss store.Store) {
+	startCount := store.Must(ss.Channel().AnalyticsFeedCount()).(int64)
+
+	withFeed := store.Must(ss.Channel().Save(&model.Channel{
+		TeamId:      model.NewId(),
+		DisplayName: "With Feed",
+		Name:        "zz" + model.NewId() + "b",
+		Type:        model.CHANNEL_OPEN,
+		EnableFeed:  true,
+	}, -1)).(*model.Channel)
+
+	store.Must(ss.Channel().Save(&model.Channel{
+		TeamId:      model.NewId(),
+		DisplayName: "Without Feed",
+		Name:        "zz" + model.NewId() + "b",
+		Type:        model.CHANNEL_OPEN,
+	}, -1))
+
+	// Private channels can't have feeds
+	store.Must(ss.Channel().Save(&model.Channel{
+		TeamId:      model.NewId(),
+		DisplayName: "Private",
+		Name:        "zz" + model.NewId() + "b",
+		Type:        model.CHANNEL_PRIVATE,
+		EnableFeed:  true,
+	}, -1))
+
+	assert.Equal(t, startCount+1, store.Must(ss.Channel().AnalyticsFeedCount()).(int64))
+
+	store.Must(ss.Channel().Delete(withFeed.Id, model.GetMillis()))
+	assert.Equal(t, startCount, store.Must(ss.Channel().AnalyticsFeedCount()).(int64), "deleted channels shouldn't be counted")
+}
+
 func testChannelStoreAnalyticsDeletedTypeCount(t *testing.T, ss store.Store) {
 	o1 := model.Channel{}
 	o1.TeamId = model.NewId()
@@ -2156,6 +2677,185 @@ func testChannelStoreGetPinnedPosts(t *testing.T, ss store.Store) {
 	}
 }
 
+func testChannelStoreGetByIconEmojiName(t *testing.T, ss store.Store) {
+	emojiName := "emoji" + model.NewId()
+	saveChannel := func(iconEmojiName string) *model.Channel {
+		return store.Must(ss.Channel().Save(&model.Channel{
+			TeamId:        model.NewId(),
+			DisplayName:   "Name",
+			Name:          "zz" + model.NewId() + "b",
+			Type:          model.CHANNEL_OPEN,
+			IconEmojiName: iconEmojiName,
+		}, -1)).(*model.Channel)
+	}
+
+	withEmoji := saveChannel(emojiName)
+	deleted := saveChannel(emojiName)
+	store.Must(ss.Channel().Delete(deleted.Id, model.GetMillis()))
+	saveChannel("smile")
+	saveChannel("")
+
+	channels := store.Must(ss.Channel().GetByIconEmojiName(emojiName)).([]*model.Channel)
+	var ids []string
+	for _, channel := range channels {
+		ids = append(ids, channel.Id)
+	}
+	assert.ElementsMatch(t, []string{withEmoji.Id, deleted.Id}, ids)
+
+	assert.Empty(t, store.Must(ss.Channel().GetByIconEmojiName("emoji"+model.NewId())).([]*model.Channel))
+}
+
+func testChannelStoreGetInactivePublicChannels(t *testing.T, ss store.Store) {
+	teamId := model.NewId()
+	saveChannel := func(channelType string) *model.Channel {
+		return store.Must(ss.Channel().Save(&model.Channel{
+			TeamId:      teamId,
+			DisplayName: "Name",
+			Name:        "zz" + model.NewId() + "b",
+			Type:        channelType,
+		}, -1)).(*model.Channel)
+	}
+	savePost := func(channelId string, postType string, createAt int64) {
+		store.Must(ss.Post().Save(&model.Post{
+			UserId:    model.NewId(),
+			ChannelId: channelId,
+			Message:   "test",
+			Type:      postType,
+			CreateAt:  createAt,
+		}))
+	}
+
+	inactiveSince := model.GetMillis() + 60*60*1000
+
+	inactive := saveChannel(model.CHANNEL_OPEN)
+	savePost(inactive.Id, model.POST_DEFAULT, inactiveSince-2000)
+	savePost(inactive.Id, model.POST_JOIN_CHANNEL, inactiveSince+1000)
+
+	warned := saveChannel(model.CHANNEL_OPEN)
+	savePost(warned.Id, model.POST_CHANNEL_INACTIVE, inactiveSince+2000)
+
+	active := saveChannel(model.CHANNEL_OPEN)
+	savePost(active.Id, model.POST_DEFAULT, inactiveSince+1000)
+
+	private := saveChannel(model.CHANNEL_PRIVATE)
+
+	deleted := saveChannel(model.CHANNEL_OPEN)
+	store.Must(ss.Channel().Delete(deleted.Id, model.GetMillis()))
+
+	activity := map[string]*model.ChannelActivity{}
+	afterChannelId := ""
+	for {
+		result := <-ss.Channel().GetInactivePublicChannels(inactiveSince, afterChannelId, 100)
+		require.Nil(t, result.Err)
+
+		batch := result.Data.([]*model.ChannelActivity)
+		for _, a := range batch {
+			activity[a.ChannelId] = a
+		}
+
+		if len(batch) < 100 {
+			break
+		}
+		afterChannelId = batch[len(batch)-1].ChannelId
+	}
+
+	require.NotNil(t, activity[inactive.Id])
+	assert.Equal(t, inactiveSince-2000, activity[inactive.Id].LastActivityAt, "system messages aren't activity")
+	assert.Equal(t, int64(0), activity[inactive.Id].WarnedAt)
+
+	require.NotNil(t, activity[warned.Id])
+	assert.Equal(t, warned.CreateAt, activity[warned.Id].LastActivityAt)
+	assert.Equal(t, inactiveSince+2000, activity[warned.Id].WarnedAt)
+
+	assert.Nil(t, activity[active.Id])
+	assert.Nil(t, activity[private.Id])
+	assert.Nil(t, activity[deleted.Id])
+}
+
+func testChannelStorePermanentDeletion(t *testing.T, ss store.Store) {
+	teamId := model.NewId()
+	saveChannel := func() *model.Channel {
+		return store.Must(ss.Channel().Save(&model.Channel{
+			TeamId:      teamId,
+			DisplayName: "Name",
+			Name:        "zz" + model.NewId() + "b",
+			Type:        model.CHANNEL_OPEN,
+		}, -1)).(*model.Channel)
+	}
+	dueChannelIds := func(before int64) []string {
+		result := <-ss.Channel().GetDueForPermanentDeletion(before, 10000)
+		require.Nil(t, result.Err)
+
+		var channelIds []string
+		for _, channel := range result.Data.([]*model.Channel) {
+			if channel.TeamId == teamId {
+				channelIds = append(channelIds, channel.Id)
+			}
+		}
+		return channelIds
+	}
+
+	now := model.GetMillis()
+
+	active := saveChannel()
+	result := <-ss.Channel().SetPermanentDeleteAt(active.Id, now, now)
+	require.NotNil(t, result.Err, "only archived channels can be marked")
+	assert.Equal(t, http.StatusBadRequest, result.Err.StatusCode)
+
+	archived := saveChannel()
+	store.Must(ss.Channel().Delete(archived.Id, now))
+
+	soon := saveChannel()
+	store.Must(ss.Channel().Delete(soon.Id, now))
+	store.Must(ss.Channel().SetPermanentDeleteAt(soon.Id, now+1000, now))
+
+	later := saveChannel()
+	store.Must(ss.Channel().Delete(later.Id, now))
+	store.Must(ss.Channel().SetPermanentDeleteAt(later.Id, now+2000, now))
+
+	assert.Empty(t, dueChannelIds(now))
+	assert.Equal(t, []string{soon.Id}, dueChannelIds(now+1000))
+	assert.Equal(t, []string{soon.Id, later.Id}, dueChannelIds(now+2000))
+
+	t.Run("hidden from listings", func(t *testing.T) {
+		result := <-ss.Channel().GetDeleted(teamId, 0, 100)
+		require.Nil(t, result.Err)
+		list := *result.Data.(*model.ChannelList)
+		require.Len(t, list, 1)
+		assert.Equal(t, archived.Id, list[0].Id)
+
+		result = <-ss.Channel().GetAll(teamId)
+		require.Nil(t, result.Err)
+		var channelIds []string
+		for _, channel := range result.Data.([]*model.Channel) {
+			channelIds = append(channelIds, channel.Id)
+		}
+		assert.ElementsMatch(t, []string{active.Id, archived.Id}, channelIds)
+
+		channel := store.Must(ss.Channel().Get(soon.Id, false)).(*model.Channel)
+		assert.Equal(t, now+1000, channel.PermanentDeleteAt, "pending channels can still be looked up directly")
+	})
+
+	t.Run("not overwritten by updates", func(t *testing.T) {
+		stale := *later
+		stale.Header = "updated"
+		store.Must(ss.Channel().Update(&stale))
+
+		channel := store.Must(ss.Channel().Get(later.Id, false)).(*model.Channel)
+		assert.Equal(t, "updated", channel.Header)
+		assert.Equal(t, now+2000, channel.PermanentDeleteAt)
+	})
+
+	t.Run("restoring cancels deletion", func(t *testing.T) {
+		store.Must(ss.Channel().Restore(soon.Id, now))
+
+		channel := store.Must(ss.Channel().Get(soon.Id, false)).(*model.Channel)
+		assert.Equal(t, int64(0), channel.DeleteAt)
+		assert.Equal(t, int64(0), channel.PermanentDeleteAt)
+		assert.Equal(t, []string{later.Id}, dueChannelIds(now+2000))
+	})
+}
+
 func testChannelStoreMaxChannelsPerTeam(t *testing.T, ss store.Store) {
 	channel := &model.Channel{
 		TeamId:      model.NewId(),
@@ -2171,3 +2871,40 @@ func testChannelStoreMaxChannelsPerTeam(t *testing.T, ss store.Store) {
 	result = <-ss.Channel().Save(channel, 1)
 	assert.Nil(t, result.Err)
 }
+
+func testChannelStoreGetBatchForTeam(t *testing.T, ss store.Store) {
+	teamId := model.NewId()
+	var channelIds []string
+	for _, channelType := range []string{model.CHANNEL_OPEN, model.CHANNEL_PRIVATE, model.CHANNEL_OPEN} {
+		channel := store.Must(ss.Channel().Save(&model.Channel{
+			TeamId:      teamId,
+			DisplayName: "Name",
+			Name:        "zz" + model.NewId() + "b",
+			Type:        channelType,
+		}, -1)).(*model.Channel)
+		channelIds = append(channelIds, channel.Id)
+	}
+	store.Must(ss.Channel().Delete(channelIds[2], model.GetMillis()))
+	sort.Strings(channelIds)
+
+	store.Must(ss.Channel().Save(&model.Channel{
+		TeamId:      model.NewId(),
+		DisplayName: "Name",
+		Name:        "zz" + model.NewId() + "b",
+		Type:        model.CHANNEL_OPEN,
+	}, -1))
+
+	batchIds := func(limit int) []string {
+		result := <-ss.Channel().GetBatchForTeam(teamId, limit)
+		require.Nil(t, result.Err)
+
+		var ids []string
+		for _, channel := range result.Data.([]*model.Channel) {
+			ids = append(ids, channel.Id)
+		}
+		return ids
+	}
+
+	assert.Equal(t, channelIds[:2], batchIds(2))
+	assert.Equal(t, channelIds, batchIds(10), "archived channels should be included")
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package storetest
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func testChannelStoreCreateInitialSidebarCategories(t *testing.T, ss store.Store) {
+	userId := model.NewId()
+	teamId := model.NewId()
+
+	favorite := store.Must(ss.Channel().Save(&model.Channel{
+		TeamId:      teamId,
+		DisplayName: "A",
+		Name:        "zz" + model.NewId() + "b",
+		Type:        model.CHANNEL_OPEN,
+	}, -1)).(*model.Channel)
+	store.Must(ss.Channel().SaveMember(&model.ChannelMember{ChannelId: favorite.Id, UserId: userId, NotifyProps: model.GetDefaultChannelNotifyProps()}))
+
+	otherTeamFavorite := store.Must(ss.Channel().Save(&model.Channel{
+		TeamId:      model.NewId(),
+		DisplayName: "B",
+		Name:        "zz" + model.NewId() + "b",
+		Type:        model.CHANNEL_OPEN,
+	}, -1)).(*model.Channel)
+	store.Must(ss.Channel().SaveMember(&model.ChannelMember{ChannelId: otherTeamFavorite.Id, UserId: userId, NotifyProps: model.GetDefaultChannelNotifyProps()}))
+
+	store.Must(ss.Preference().Save(&model.Preferences{
+		{UserId: userId, Category: model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, Name: favorite.Id, Value: "true"},
+		{UserId: userId, Category: model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, Name: otherTeamFavorite.Id, Value: "true"},
+	}))
+
+	categories := store.Must(ss.Channel().CreateInitialSidebarCategories(userId, teamId)).(*model.OrderedSidebarCategories)
+	require.Len(t, categories.Categories, 3)
+	assert.Equal(t, store.Must(ss.Channel().GetSidebarCategories(userId, teamId)), categories)
+	assert.Equal(t, model.SIDEBAR_CATEGORY_FAVORITES, categories.Categories[0].Type)
+	assert.Equal(t, []string{favorite.Id}, categories.Categories[0].Channels)
+	assert.Equal(t, model.SIDEBAR_CATEGORY_CHANNELS, categories.Categories[1].Type)
+	assert.Equal(t, []string{}, categories.Categories[1].Channels)
+	assert.Equal(t, model.SIDEBAR_CATEGORY_DIRECT_MESSAGES, categories.Categories[2].Type)
+
+	// Calling it again shouldn't create duplicates
+	store.Must(ss.Channel().CreateInitialSidebarCategories(userId, teamId))
+
+	categories = store.Must(ss.Channel().GetSidebarCategories(userId, teamId)).(*model.OrderedSidebarCategories)
+	assert.Len(t, categories.Categories, 3)
+
+	t.Run("at the same time", func(t *testing.T) {
+		userId := model.NewId()
+		teamId := model.NewId()
+
+		var wg sync.WaitGroup
+		results := make([]store.StoreResult, 5)
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i] = <-ss.Channel().CreateInitialSidebarCategories(userId, teamId)
+			}(i)
+		}
+		wg.Wait()
+
+		for _, result := range results {
+			require.Nil(t, result.Err)
+			assert.Len(t, result.Data.(*model.OrderedSidebarCategories).Categories, 3)
+		}
+
+		categories := store.Must(ss.Channel().GetSidebarCategories(userId, teamId)).(*model.OrderedSidebarCategories)
+		require.Len(t, categories.Categories, 3)
+		assert.Equal(t, model.DefaultSidebarCategoryId(model.SIDEBAR_CATEGORY_FAVORITES, userId, teamId), categories.Categories[0].Id)
+	})
+}
+
+func testChannelStoreSidebarCategories(t *testing.T, ss store.Store) {
+	userId := model.NewId()
+	teamId := model.NewId()
+	channelId1 := model.NewId()
+	channelId2 := model.NewId()
+
+	store.Must(ss.Channel().CreateInitialSidebarCategories(userId, teamId))
+
+	category1 := store.Must(ss.Channel().CreateSidebarCategory(&model.SidebarCategoryWithChannels{
+		SidebarCategory: model.SidebarCategory{UserId: userId, TeamId: teamId, DisplayName: "First"},
+		Channels:        []string{channelId1, channelId2},
+	})).(*model.SidebarCategoryWithChannels)
+	assert.Equal(t, model.SIDEBAR_CATEGORY_CUSTOM, category1.Type)
+
+	category2 := store.Must(ss.Channel().CreateSidebarCategory(&model.SidebarCategoryWithChannels{
+		SidebarCategory: model.SidebarCategory{UserId: userId, TeamId: teamId, DisplayName: "Second"},
+		Channels:        []string{channelId2},
+	})).(*model.SidebarCategoryWithChannels)
+
+	categories := store.Must(ss.Channel().GetSidebarCategories(userId, teamId)).(*model.OrderedSidebarCategories)
+	require.Len(t, categories.Order, 5)
+	assert.Equal(t, category1.Id, categories.Order[3])
+	assert.Equal(t, category2.Id, categories.Order[4])
+
+	// Adding a channel to another category removes it from the first one
+	category := store.Must(ss.Channel().GetSidebarCategory(category1.Id)).(*model.SidebarCategoryWithChannels)
+	assert.Equal(t, []string{channelId1}, category.Channels)
+
+	category1.DisplayName = "Renamed"
+	category1.Channels = []string{channelId2, channelId1}
+	store.Must(ss.Channel().UpdateSidebarCategories(userId, teamId, []*model.SidebarCategoryWithChannels{category1}))
+
+	category = store.Must(ss.Channel().GetSidebarCategory(category1.Id)).(*model.SidebarCategoryWithChannels)
+	assert.Equal(t, "Renamed", category.DisplayName)
+	assert.Equal(t, []string{channelId2, channelId1}, category.Channels)
+
+	category = store.Must(ss.Channel().GetSidebarCategory(category2.Id)).(*model.SidebarCategoryWithChannels)
+	assert.Equal(t, []string{}, category.Channels)
+
+	// Categories belonging to other users can't be updated
+	result := <-ss.Channel().UpdateSidebarCategories(model.NewId(), teamId, []*model.SidebarCategoryWithChannels{category1})
+	assert.NotNil(t, result.Err)
+
+	newOrder := []string{category2.Id, categories.Order[0], categories.Order[1], categories.Order[2], category1.Id}
+	store.Must(ss.Channel().UpdateSidebarCategoryOrder(userId, teamId, newOrder))
+
+	categories = store.Must(ss.Channel().GetSidebarCategories(userId, teamId)).(*model.OrderedSidebarCategories)
+	assert.Equal(t, newOrder, categories.Order)
+
+	store.Must(ss.Channel().RemoveChannelFromSidebarCategories(userId, channelId2))
+
+	category = store.Must(ss.Channel().GetSidebarCategory(category1.Id)).(*model.SidebarCategoryWithChannels)
+	assert.Equal(t, []string{channelId1}, category.Channels)
+
+	store.Must(ss.Channel().DeleteSidebarCategory(category1.Id))
+
+	result = <-ss.Channel().GetSidebarCategory(category1.Id)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "store.sql_channel.sidebar_categories.not_found.app_error", result.Err.Id)
+
+	store.Must(ss.Channel().DeleteSidebarCategoriesForTeamMember(userId, teamId))
+
+	categories = store.Must(ss.Channel().GetSidebarCategories(userId, teamId)).(*model.OrderedSidebarCategories)
+	assert.Len(t, categories.Categories, 0)
+}
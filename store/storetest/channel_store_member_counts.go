@@ -0,0 +1,134 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package storetest
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func assertMemberCounts(t *testing.T, ss store.Store, channelId string, memberCount int64, guestCount int64) {
+	result := <-ss.Channel().GetMemberCount(channelId, false)
+	require.Nil(t, result.Err)
+	assert.Equal(t, memberCount, result.Data.(int64), "wrong member count")
+
+	result = <-ss.Channel().GetGuestCount(channelId)
+	require.Nil(t, result.Err)
+	assert.Equal(t, guestCount, result.Data.(int64), "wrong guest count")
+}
+
+func testChannelStoreMemberCounts(t *testing.T, ss store.Store) {
+	channel := store.Must(ss.Channel().Save(&model.Channel{
+		TeamId:      model.NewId(),
+		DisplayName: "Channel",
+		Name:        "zz" + model.NewId() + "b",
+		Type:        model.CHANNEL_OPEN,
+		MemberCount: 10,
+	}, -1)).(*model.Channel)
+
+	t.Run("new channels have no members", func(t *testing.T) {
+		assertMemberCounts(t, ss, channel.Id, 0, 0)
+	})
+
+	user := store.Must(ss.User().Save(&model.User{Email: model.NewId()})).(*model.User)
+	guest := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Roles: model.SYSTEM_GUEST_ROLE_ID})).(*model.User)
+
+	store.Must(ss.Channel().SaveMember(&model.ChannelMember{ChannelId: channel.Id, UserId: user.Id, NotifyProps: model.GetDefaultChannelNotifyProps()}))
+	store.Must(ss.Channel().SaveMember(&model.ChannelMember{ChannelId: channel.Id, UserId: guest.Id, NotifyProps: model.GetDefaultChannelNotifyProps()}))
+
+	t.Run("joining", func(t *testing.T) {
+		assertMemberCounts(t, ss, channel.Id, 2, 1)
+	})
+
+	t.Run("updating the channel keeps the counts", func(t *testing.T) {
+		channel.Header = "header"
+		store.Must(ss.Channel().Update(channel))
+
+		assertMemberCounts(t, ss, channel.Id, 2, 1)
+	})
+
+	t.Run("deactivation", func(t *testing.T) {
+		guest.DeleteAt = model.GetMillis()
+		store.Must(ss.User().Update(guest, true))
+		assertMemberCounts(t, ss, channel.Id, 1, 0)
+
+		guest.DeleteAt = 0
+		store.Must(ss.User().Update(guest, true))
+		assertMemberCounts(t, ss, channel.Id, 2, 1)
+	})
+
+	t.Run("promotion and demotion", func(t *testing.T) {
+		require.Nil(t, (<-ss.User().PromoteGuestToUser(guest.Id)).Err)
+		assertMemberCounts(t, ss, channel.Id, 2, 0)
+
+		require.Nil(t, (<-ss.User().DemoteUserToGuest(guest.Id)).Err)
+		assertMemberCounts(t, ss, channel.Id, 2, 1)
+	})
+
+	t.Run("leaving", func(t *testing.T) {
+		store.Must(ss.Channel().RemoveMember(channel.Id, guest.Id))
+		assertMemberCounts(t, ss, channel.Id, 1, 0)
+
+		// Removing someone who isn't a member changes nothing
+		store.Must(ss.Channel().RemoveMember(channel.Id, guest.Id))
+		assertMemberCounts(t, ss, channel.Id, 1, 0)
+	})
+
+	t.Run("permanently deleting a user's memberships", func(t *testing.T) {
+		store.Must(ss.Channel().PermanentDeleteMembersByUser(user.Id))
+		assertMemberCounts(t, ss, channel.Id, 0, 0)
+	})
+}
+
+func testChannelStoreMemberCountsConcurrentJoins(t *testing.T, ss store.Store) {
+	channel := store.Must(ss.Channel().Save(&model.Channel{
+		TeamId:      model.NewId(),
+		DisplayName: "Channel",
+		Name:        "zz" + model.NewId() + "b",
+		Type:        model.CHANNEL_OPEN,
+	}, -1)).(*model.Channel)
+
+	const userCount = 20
+
+	userIds := make([]string, userCount)
+	for i := range userIds {
+		userIds[i] = store.Must(ss.User().Save(&model.User{Email: model.NewId()})).(*model.User).Id
+	}
+
+	runConcurrently := func(f func(userId string) store.StoreChannel) {
+		var wg sync.WaitGroup
+		errs := make(chan *model.AppError, userCount)
+
+		for _, userId := range userIds {
+			wg.Add(1)
+			go func(userId string) {
+				defer wg.Done()
+				errs <- (<-f(userId)).Err
+			}(userId)
+		}
+
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			require.Nil(t, err)
+		}
+	}
+
+	runConcurrently(func(userId string) store.StoreChannel {
+		return ss.Channel().SaveMember(&model.ChannelMember{ChannelId: channel.Id, UserId: userId, NotifyProps: model.GetDefaultChannelNotifyProps()})
+	})
+	assertMemberCounts(t, ss, channel.Id, userCount, 0)
+
+	runConcurrently(func(userId string) store.StoreChannel {
+		return ss.Channel().RemoveMember(channel.Id, userId)
+	})
+	assertMemberCounts(t, ss, channel.Id, 0, 0)
+}
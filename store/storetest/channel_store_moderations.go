@@ -0,0 +1,67 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package storetest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func testChannelStoreModeratedPermissions(t *testing.T, ss store.Store) {
+	channel := store.Must(ss.Channel().Save(&model.Channel{
+		TeamId:      model.NewId(),
+		DisplayName: "Announcements",
+		Name:        "zz" + model.NewId() + "b",
+		Type:        model.CHANNEL_OPEN,
+	}, -1)).(*model.Channel)
+
+	empty := &model.ChannelModeratedPermissions{Guests: []string{}, Members: []string{}}
+
+	result := <-ss.Channel().GetModeratedPermissions(channel.Id)
+	require.Nil(t, result.Err)
+	assert.Equal(t, empty, result.Data.(*model.ChannelModeratedPermissions))
+
+	result = <-ss.Channel().UpdateModeratedPermissions(channel.Id, &model.ChannelModeratedPermissions{
+		Guests: []string{model.CHANNEL_MODERATED_PERMISSION_CREATE_REACTIONS, model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS},
+		Members: []string{
+			model.CHANNEL_MODERATED_PERMISSION_MANAGE_MEMBERS,
+			model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS,
+		},
+	})
+	require.Nil(t, result.Err)
+
+	result = <-ss.Channel().GetModeratedPermissions(channel.Id)
+	require.Nil(t, result.Err)
+	assert.Equal(t, &model.ChannelModeratedPermissions{
+		Guests:  []string{model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS, model.CHANNEL_MODERATED_PERMISSION_CREATE_REACTIONS},
+		Members: []string{model.CHANNEL_MODERATED_PERMISSION_CREATE_POSTS, model.CHANNEL_MODERATED_PERMISSION_MANAGE_MEMBERS},
+	}, result.Data.(*model.ChannelModeratedPermissions))
+
+	result = <-ss.Channel().GetModeratedPermissions(model.NewId())
+	require.Nil(t, result.Err)
+	assert.Equal(t, empty, result.Data.(*model.ChannelModeratedPermissions))
+
+	result = <-ss.Channel().UpdateModeratedPermissions(channel.Id, &model.ChannelModeratedPermissions{
+		Members: []string{model.CHANNEL_MODERATED_PERMISSION_CREATE_REACTIONS},
+	})
+	require.Nil(t, result.Err)
+
+	result = <-ss.Channel().GetModeratedPermissions(channel.Id)
+	require.Nil(t, result.Err)
+	assert.Equal(t, &model.ChannelModeratedPermissions{
+		Guests:  []string{},
+		Members: []string{model.CHANNEL_MODERATED_PERMISSION_CREATE_REACTIONS},
+	}, result.Data.(*model.ChannelModeratedPermissions))
+
+	store.Must(ss.Channel().PermanentDelete(channel.Id))
+
+	result = <-ss.Channel().GetModeratedPermissions(channel.Id)
+	require.Nil(t, result.Err)
+	assert.Equal(t, empty, result.Data.(*model.ChannelModeratedPermissions))
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package storetest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func testChannelStoreNameHistory(t *testing.T, ss store.Store) {
+	teamId := model.NewId()
+
+	channel := store.Must(ss.Channel().Save(&model.Channel{
+		TeamId:      teamId,
+		DisplayName: "Renamed",
+		Name:        "zz" + model.NewId() + "b",
+		Type:        model.CHANNEL_OPEN,
+	}, -1)).(*model.Channel)
+	other := store.Must(ss.Channel().Save(&model.Channel{
+		TeamId:      teamId,
+		DisplayName: "Other",
+		Name:        "zz" + model.NewId() + "b",
+		Type:        model.CHANNEL_OPEN,
+	}, -1)).(*model.Channel)
+
+	oldName := "zz" + model.NewId() + "b"
+	result := <-ss.Channel().SaveNameHistory(&model.ChannelNameHistory{TeamId: teamId, Name: oldName, ChannelId: channel.Id, CreateAt: 1000}, 2)
+	require.Nil(t, result.Err)
+
+	t.Run("get by name", func(t *testing.T) {
+		result := <-ss.Channel().GetNameHistoryByName(teamId, oldName)
+		require.Nil(t, result.Err)
+		assert.Equal(t, channel.Id, result.Data.(*model.ChannelNameHistory).ChannelId)
+
+		result = <-ss.Channel().GetNameHistoryByName(model.NewId(), oldName)
+		require.NotNil(t, result.Err)
+		assert.Equal(t, "store.sql_channel.name_history.get_by_name.missing.app_error", result.Err.Id)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		result := <-ss.Channel().SaveNameHistory(&model.ChannelNameHistory{TeamId: teamId, Name: "", ChannelId: channel.Id}, 2)
+		require.NotNil(t, result.Err)
+	})
+
+	t.Run("taken over by another channel", func(t *testing.T) {
+		sharedName := "zz" + model.NewId() + "b"
+		store.Must(ss.Channel().SaveNameHistory(&model.ChannelNameHistory{TeamId: teamId, Name: sharedName, ChannelId: other.Id, CreateAt: 1000}, 2))
+		store.Must(ss.Channel().SaveNameHistory(&model.ChannelNameHistory{TeamId: teamId, Name: sharedName, ChannelId: channel.Id, CreateAt: 2000}, 2))
+
+		result := <-ss.Channel().GetNameHistoryByName(teamId, sharedName)
+		require.Nil(t, result.Err)
+		assert.Equal(t, channel.Id, result.Data.(*model.ChannelNameHistory).ChannelId)
+
+		result = <-ss.Channel().GetNameHistory(other.Id)
+		require.Nil(t, result.Err)
+		assert.Len(t, result.Data.([]*model.ChannelNameHistory), 0)
+
+		store.Must(ss.Channel().DeleteNameHistory(teamId, sharedName))
+	})
+
+	t.Run("capped per channel", func(t *testing.T) {
+		newerName := "zz" + model.NewId() + "b"
+		newestName := "zz" + model.NewId() + "b"
+		store.Must(ss.Channel().SaveNameHistory(&model.ChannelNameHistory{TeamId: teamId, Name: newerName, ChannelId: channel.Id, CreateAt: 3000}, 2))
+		store.Must(ss.Channel().SaveNameHistory(&model.ChannelNameHistory{TeamId: teamId, Name: newestName, ChannelId: channel.Id, CreateAt: 4000}, 2))
+
+		result := <-ss.Channel().GetNameHistory(channel.Id)
+		require.Nil(t, result.Err)
+		history := result.Data.([]*model.ChannelNameHistory)
+		require.Len(t, history, 2)
+		assert.Equal(t, newestName, history[0].Name)
+		assert.Equal(t, newerName, history[1].Name)
+
+		result = <-ss.Channel().GetNameHistoryByName(teamId, oldName)
+		require.NotNil(t, result.Err)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		history := store.Must(ss.Channel().GetNameHistory(channel.Id)).([]*model.ChannelNameHistory)
+		require.NotEmpty(t, history)
+
+		store.Must(ss.Channel().DeleteNameHistory(teamId, history[0].Name))
+
+		result := <-ss.Channel().GetNameHistoryByName(teamId, history[0].Name)
+		require.NotNil(t, result.Err)
+	})
+
+	t.Run("deleted with the channel", func(t *testing.T) {
+		store.Must(ss.Channel().PermanentDelete(channel.Id))
+
+		result := <-ss.Channel().GetNameHistory(channel.Id)
+		require.Nil(t, result.Err)
+		assert.Len(t, result.Data.([]*model.ChannelNameHistory), 0)
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/store"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestComplianceStore(t *testing.T, ss store.Store) {
@@ -20,6 +21,7 @@ func TestComplianceStore(t *testing.T, ss store.Store) {
 	t.Run("MessageExportPrivateChannel", func(t *testing.T) { testMessageExportPrivateChannel(t, ss) })
 	t.Run("MessageExportDirectMessageChannel", func(t *testing.T) { testMessageExportDirectMessageChannel(t, ss) })
 	t.Run("MessageExportGroupMessageChannel", func(t *testing.T) { testMessageExportGroupMessageChannel(t, ss) })
+	t.Run("ExportPosts", func(t *testing.T) { testComplianceExportPosts(t, ss) })
 }
 
 func testComplianceStore(t *testing.T, ss store.Store) {
@@ -696,3 +698,102 @@ func testMessageExportGroupMessageChannel(t *testing.T, ss store.Store) {
 	assert.Equal(t, user1.Email, *messageExportMap[post.Id].UserEmail)
 	assert.Equal(t, user1.Username, *messageExportMap[post.Id].Username)
 }
+
+func testComplianceExportPosts(t *testing.T, ss store.Store) {
+	team := store.Must(ss.Team().Save(&model.Team{
+		DisplayName: "DisplayName",
+		Name:        "zz" + model.NewId() + "b",
+		Email:       model.NewId() + "@nowhere.com",
+		Type:        model.TEAM_OPEN,
+	})).(*model.Team)
+
+	channel := store.Must(ss.Channel().Save(&model.Channel{
+		TeamId:      team.Id,
+		DisplayName: "Channel",
+		Name:        "zz" + model.NewId() + "b",
+		Type:        model.CHANNEL_OPEN,
+	}, -1)).(*model.Channel)
+
+	user := store.Must(ss.User().Save(&model.User{
+		Email:    model.NewId() + "@nowhere.com",
+		Username: "u" + model.NewId(),
+	})).(*model.User)
+
+	dm := store.Must(ss.Channel().CreateDirectChannel(user.Id, model.NewId())).(*model.Channel)
+
+	start := model.GetMillis() - 100000
+
+	save := func(channelId string, createAt int64) *model.Post {
+		return store.Must(ss.Post().Save(&model.Post{
+			ChannelId: channelId,
+			UserId:    user.Id,
+			Message:   "zz" + model.NewId(),
+			CreateAt:  createAt,
+		})).(*model.Post)
+	}
+
+	before := save(channel.Id, start-1)
+	plain := save(channel.Id, start)
+	edited := save(channel.Id, start+10)
+	deleted := save(channel.Id, start+20)
+	direct := save(dm.Id, start+30)
+	after := save(channel.Id, start+1000)
+
+	editedCopy := &model.Post{}
+	*editedCopy = *edited
+	editedCopy.Message = "edited " + edited.Message
+	store.Must(ss.Post().Update(editedCopy, edited))
+
+	store.Must(ss.Post().Delete(deleted.Id, model.GetMillis()))
+
+	var exported []*model.ComplianceExportPost
+	afterCreateAt, afterId := int64(0), ""
+	for {
+		batch := store.Must(ss.Compliance().ExportPosts(start, start+100, afterCreateAt, afterId, 2)).([]*model.ComplianceExportPost)
+		if len(batch) == 0 {
+			break
+		}
+		require.True(t, len(batch) <= 2)
+
+		exported = append(exported, batch...)
+		afterCreateAt, afterId = batch[len(batch)-1].PostCreateAt, batch[len(batch)-1].PostId
+	}
+
+	byId := make(map[string]*model.ComplianceExportPost)
+	var previousVersion *model.ComplianceExportPost
+	for i, post := range exported {
+		if i > 0 {
+			assert.True(t, exported[i-1].PostCreateAt <= post.PostCreateAt, "posts should be ordered by CreateAt")
+		}
+		if post.PostOriginalId == edited.Id {
+			previousVersion = post
+		}
+		byId[post.PostId] = post
+	}
+
+	require.Len(t, exported, 5)
+	assert.Nil(t, byId[before.Id])
+	assert.Nil(t, byId[after.Id])
+
+	require.NotNil(t, byId[plain.Id])
+	assert.Equal(t, "", byId[plain.Id].Status())
+	assert.Equal(t, team.Name, byId[plain.Id].TeamName)
+	assert.Equal(t, channel.Name, byId[plain.Id].ChannelName)
+	assert.Equal(t, user.Username, byId[plain.Id].UserUsername)
+
+	require.NotNil(t, byId[edited.Id])
+	assert.Equal(t, model.COMPLIANCE_EXPORT_POST_STATUS_EDITED, byId[edited.Id].Status())
+	assert.Equal(t, editedCopy.Message, byId[edited.Id].PostMessage)
+
+	require.NotNil(t, previousVersion)
+	assert.Equal(t, model.COMPLIANCE_EXPORT_POST_STATUS_PREVIOUS_VERSION, previousVersion.Status())
+	assert.Equal(t, edited.Message, previousVersion.PostMessage)
+
+	require.NotNil(t, byId[deleted.Id])
+	assert.Equal(t, model.COMPLIANCE_EXPORT_POST_STATUS_DELETED, byId[deleted.Id].Status())
+	assert.NotZero(t, byId[deleted.Id].PostDeleteAt)
+
+	require.NotNil(t, byId[direct.Id])
+	assert.Equal(t, "", byId[direct.Id].TeamName)
+	assert.Equal(t, model.CHANNEL_DIRECT, byId[direct.Id].ChannelType)
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package storetest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func TestConfigurationHistoryStore(t *testing.T, ss store.Store) {
+	t.Run("SaveAndGet", func(t *testing.T) { testConfigurationHistoryStoreSaveAndGet(t, ss) })
+	t.Run("GetPageAndPrevious", func(t *testing.T) { testConfigurationHistoryStoreGetPageAndPrevious(t, ss) })
+	t.Run("PermanentDeleteAllButLatest", func(t *testing.T) { testConfigurationHistoryStorePermanentDeleteAllButLatest(t, ss) })
+}
+
+func saveTestConfigurationHistory(t *testing.T, ss store.Store, createAt int64) *model.ConfigurationHistory {
+	result := <-ss.ConfigurationHistory().Save(&model.ConfigurationHistory{
+		CreateAt: createAt,
+		UserId:   model.NewId(),
+		Source:   model.CONFIGURATION_HISTORY_SOURCE_API,
+		Summary:  "TeamSettings.SiteName",
+		Config:   "{}",
+		Secrets:  "secrets",
+	})
+	require.Nil(t, result.Err)
+	return result.Data.(*model.ConfigurationHistory)
+}
+
+func testConfigurationHistoryStoreSaveAndGet(t *testing.T, ss store.Store) {
+	history := saveTestConfigurationHistory(t, ss, model.GetMillis())
+
+	result := <-ss.ConfigurationHistory().Get(history.Id)
+	require.Nil(t, result.Err)
+	assert.Equal(t, history, result.Data.(*model.ConfigurationHistory))
+
+	other := saveTestConfigurationHistory(t, ss, model.GetMillis())
+	require.Nil(t, (<-ss.ConfigurationHistory().UpdateSecrets(map[string]string{history.Id: "updated", other.Id: "also updated"})).Err)
+	result = <-ss.ConfigurationHistory().Get(history.Id)
+	require.Nil(t, result.Err)
+	assert.Equal(t, "updated", result.Data.(*model.ConfigurationHistory).Secrets)
+	result = <-ss.ConfigurationHistory().Get(other.Id)
+	require.Nil(t, result.Err)
+	assert.Equal(t, "also updated", result.Data.(*model.ConfigurationHistory).Secrets)
+
+	result = <-ss.ConfigurationHistory().Get(model.NewId())
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+
+	result = <-ss.ConfigurationHistory().Save(&model.ConfigurationHistory{Source: "other", Config: "{}"})
+	assert.NotNil(t, result.Err)
+}
+
+func testConfigurationHistoryStoreGetPageAndPrevious(t *testing.T, ss store.Store) {
+	// Versions saved together are usually in the same millisecond, so they're given times to order them by, which
+	// are newer than the versions saved by the other tests
+	now := model.GetMillis() + 100000
+	first := saveTestConfigurationHistory(t, ss, now)
+	second := saveTestConfigurationHistory(t, ss, now+1)
+
+	result := <-ss.ConfigurationHistory().GetPage(0, 2)
+	require.Nil(t, result.Err)
+	list := result.Data.([]*model.ConfigurationHistory)
+	require.Len(t, list, 2)
+	assert.Equal(t, second.Id, list[0].Id)
+	assert.Equal(t, first.Id, list[1].Id)
+
+	result = <-ss.ConfigurationHistory().GetPrevious(second)
+	require.Nil(t, result.Err)
+	assert.Equal(t, first.Id, result.Data.(*model.ConfigurationHistory).Id)
+
+	result = <-ss.ConfigurationHistory().GetPrevious(&model.ConfigurationHistory{Id: model.NewId(), CreateAt: 1})
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+}
+
+func testConfigurationHistoryStorePermanentDeleteAllButLatest(t *testing.T, ss store.Store) {
+	now := model.GetMillis() + 200000
+	oldest := saveTestConfigurationHistory(t, ss, now)
+	older := saveTestConfigurationHistory(t, ss, now+1)
+	newer := saveTestConfigurationHistory(t, ss, now+2)
+	newest := saveTestConfigurationHistory(t, ss, now+3)
+
+	result := <-ss.ConfigurationHistory().PermanentDeleteAllButLatest(2)
+	require.Nil(t, result.Err)
+	assert.True(t, result.Data.(int64) >= 2)
+
+	for _, deleted := range []*model.ConfigurationHistory{oldest, older} {
+		assert.NotNil(t, (<-ss.ConfigurationHistory().Get(deleted.Id)).Err)
+	}
+	for _, kept := range []*model.ConfigurationHistory{newer, newest} {
+		assert.Nil(t, (<-ss.ConfigurationHistory().Get(kept.Id)).Err)
+	}
+
+	result = <-ss.ConfigurationHistory().PermanentDeleteAllButLatest(10)
+	require.Nil(t, result.Err)
+	assert.Equal(t, int64(0), result.Data.(int64))
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package storetest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func TestCustomGroupStore(t *testing.T, ss store.Store) {
+	t.Run("SaveAndGet", func(t *testing.T) { testCustomGroupStoreSaveAndGet(t, ss) })
+	t.Run("Update", func(t *testing.T) { testCustomGroupStoreUpdate(t, ss) })
+	t.Run("Search", func(t *testing.T) { testCustomGroupStoreSearch(t, ss) })
+	t.Run("Members", func(t *testing.T) { testCustomGroupStoreMembers(t, ss) })
+	t.Run("Delete", func(t *testing.T) { testCustomGroupStoreDelete(t, ss) })
+}
+
+func makeCustomGroupForTest(ss store.Store, name string) *model.CustomGroup {
+	return store.Must(ss.CustomGroup().Save(&model.CustomGroup{
+		CreatorId:   model.NewId(),
+		Name:        name,
+		DisplayName: "Display " + name,
+	})).(*model.CustomGroup)
+}
+
+func testCustomGroupStoreSaveAndGet(t *testing.T, ss store.Store) {
+	name := "g" + model.NewId()
+	group := makeCustomGroupForTest(ss, name)
+	assert.Len(t, group.Id, 26)
+
+	result := <-ss.CustomGroup().Save(&model.CustomGroup{CreatorId: model.NewId(), Name: name, DisplayName: "Duplicate"})
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "store.sql_custom_group.save.name_exists.app_error", result.Err.Id)
+
+	result = <-ss.CustomGroup().Save(group)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "store.sql_custom_group.save.existing.app_error", result.Err.Id)
+
+	result = <-ss.CustomGroup().Get(group.Id)
+	require.Nil(t, result.Err)
+	assert.Equal(t, group, result.Data.(*model.CustomGroup))
+
+	result = <-ss.CustomGroup().GetByName(name)
+	require.Nil(t, result.Err)
+	assert.Equal(t, group.Id, result.Data.(*model.CustomGroup).Id)
+
+	result = <-ss.CustomGroup().GetByNames([]string{name, "g" + model.NewId()})
+	require.Nil(t, result.Err)
+	require.Len(t, result.Data.([]*model.CustomGroup), 1)
+	assert.Equal(t, group.Id, result.Data.([]*model.CustomGroup)[0].Id)
+
+	result = <-ss.CustomGroup().GetByNames([]string{})
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]*model.CustomGroup), 0)
+
+	result = <-ss.CustomGroup().Get(model.NewId())
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+}
+
+func testCustomGroupStoreUpdate(t *testing.T, ss store.Store) {
+	group := makeCustomGroupForTest(ss, "g"+model.NewId())
+	other := makeCustomGroupForTest(ss, "g"+model.NewId())
+
+	newName := "g" + model.NewId()
+	group.Name = newName
+	group.Description = "description"
+	store.Must(ss.CustomGroup().Update(group))
+
+	result := <-ss.CustomGroup().GetByName(newName)
+	require.Nil(t, result.Err)
+	assert.Equal(t, "description", result.Data.(*model.CustomGroup).Description)
+
+	group.Name = other.Name
+	result = <-ss.CustomGroup().Update(group)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "store.sql_custom_group.save.name_exists.app_error", result.Err.Id)
+}
+
+func testCustomGroupStoreSearch(t *testing.T, ss store.Store) {
+	prefix := "s" + model.NewId()[:10]
+
+	group1 := makeCustomGroupForTest(ss, prefix+"-frontend")
+	group2 := makeCustomGroupForTest(ss, prefix+"-backend")
+	group3 := store.Must(ss.CustomGroup().Save(&model.CustomGroup{
+		CreatorId:   model.NewId(),
+		Name:        "g" + model.NewId(),
+		DisplayName: prefix + " Display",
+	})).(*model.CustomGroup)
+
+	result := <-ss.CustomGroup().Search(prefix, 10)
+	require.Nil(t, result.Err)
+	groups := result.Data.([]*model.CustomGroup)
+	require.Len(t, groups, 3)
+	assert.Equal(t, group2.Id, groups[0].Id)
+	assert.Equal(t, group1.Id, groups[1].Id)
+	assert.Equal(t, group3.Id, groups[2].Id)
+
+	result = <-ss.CustomGroup().Search("@"+prefix+"-FRONT", 10)
+	require.Nil(t, result.Err)
+	require.Len(t, result.Data.([]*model.CustomGroup), 1)
+	assert.Equal(t, group1.Id, result.Data.([]*model.CustomGroup)[0].Id)
+
+	result = <-ss.CustomGroup().Search(prefix, 1)
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]*model.CustomGroup), 1)
+
+	result = <-ss.CustomGroup().Search(prefix+"%", 10)
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]*model.CustomGroup), 0)
+}
+
+func testCustomGroupStoreMembers(t *testing.T, ss store.Store) {
+	group := makeCustomGroupForTest(ss, "g"+model.NewId())
+	userId1 := model.NewId()
+	userId2 := model.NewId()
+
+	store.Must(ss.CustomGroup().SaveMember(&model.GroupMember{GroupId: group.Id, UserId: userId1}))
+	store.Must(ss.CustomGroup().SaveMember(&model.GroupMember{GroupId: group.Id, UserId: userId2, CreateAt: model.GetMillis() + 1}))
+
+	result := <-ss.CustomGroup().SaveMember(&model.GroupMember{GroupId: group.Id, UserId: userId1})
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "store.sql_custom_group.save_member.exists.app_error", result.Err.Id)
+
+	result = <-ss.CustomGroup().GetMembers(group.Id)
+	require.Nil(t, result.Err)
+	members := result.Data.([]*model.GroupMember)
+	require.Len(t, members, 2)
+	assert.Equal(t, userId1, members[0].UserId)
+	assert.Equal(t, userId2, members[1].UserId)
+
+	store.Must(ss.CustomGroup().RemoveMember(group.Id, userId1))
+	store.Must(ss.CustomGroup().PermanentDeleteMembersByUser(userId2))
+
+	result = <-ss.CustomGroup().GetMembers(group.Id)
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]*model.GroupMember), 0)
+}
+
+func testCustomGroupStoreDelete(t *testing.T, ss store.Store) {
+	group := makeCustomGroupForTest(ss, "g"+model.NewId())
+	store.Must(ss.CustomGroup().SaveMember(&model.GroupMember{GroupId: group.Id, UserId: model.NewId()}))
+
+	store.Must(ss.CustomGroup().Delete(group.Id))
+
+	result := <-ss.CustomGroup().Get(group.Id)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+
+	result = <-ss.CustomGroup().GetMembers(group.Id)
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]*model.GroupMember), 0)
+
+	// The name can be reused once the group is gone
+	makeCustomGroupForTest(ss, group.Name)
+}
@@ -11,14 +11,18 @@ import (
 	"github.com/mattermost/mattermost-server/store"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestEmojiStore(t *testing.T, ss store.Store) {
 	t.Run("EmojiSaveDelete", func(t *testing.T) { testEmojiSaveDelete(t, ss) })
 	t.Run("EmojiGet", func(t *testing.T) { testEmojiGet(t, ss) })
 	t.Run("EmojiGetByName", func(t *testing.T) { testEmojiGetByName(t, ss) })
+	t.Run("EmojiGetMultipleByName", func(t *testing.T) { testEmojiGetMultipleByName(t, ss) })
 	t.Run("EmojiGetList", func(t *testing.T) { testEmojiGetList(t, ss) })
 	t.Run("EmojiSearch", func(t *testing.T) { testEmojiSearch(t, ss) })
+	t.Run("EmojiStats", func(t *testing.T) { testEmojiStats(t, ss) })
+	t.Run("EmojiGetUnused", func(t *testing.T) { testEmojiGetUnused(t, ss) })
 }
 
 func testEmojiSaveDelete(t *testing.T, ss store.Store) {
@@ -132,6 +136,34 @@ func testEmojiGetByName(t *testing.T, ss store.Store) {
 	}
 }
 
+func testEmojiGetMultipleByName(t *testing.T, ss store.Store) {
+	emoji1 := store.Must(ss.Emoji().Save(&model.Emoji{CreatorId: model.NewId(), Name: model.NewId()})).(*model.Emoji)
+	emoji2 := store.Must(ss.Emoji().Save(&model.Emoji{CreatorId: model.NewId(), Name: model.NewId()})).(*model.Emoji)
+	deleted := store.Must(ss.Emoji().Save(&model.Emoji{CreatorId: model.NewId(), Name: model.NewId()})).(*model.Emoji)
+	defer func() {
+		store.Must(ss.Emoji().Delete(emoji1.Id, time.Now().Unix()))
+		store.Must(ss.Emoji().Delete(emoji2.Id, time.Now().Unix()))
+	}()
+	store.Must(ss.Emoji().Delete(deleted.Id, time.Now().Unix()))
+
+	t.Run("some names", func(t *testing.T) {
+		result := <-ss.Emoji().GetMultipleByName([]string{emoji1.Name, emoji2.Name, deleted.Name, model.NewId()})
+		require.Nil(t, result.Err)
+
+		var names []string
+		for _, emoji := range result.Data.([]*model.Emoji) {
+			names = append(names, emoji.Name)
+		}
+		assert.ElementsMatch(t, []string{emoji1.Name, emoji2.Name}, names)
+	})
+
+	t.Run("no names", func(t *testing.T) {
+		result := <-ss.Emoji().GetMultipleByName([]string{})
+		require.Nil(t, result.Err)
+		assert.Empty(t, result.Data.([]*model.Emoji))
+	})
+}
+
 func testEmojiGetList(t *testing.T, ss store.Store) {
 	emojis := []model.Emoji{
 		{
@@ -259,3 +291,100 @@ func testEmojiSearch(t *testing.T, ss store.Store) {
 		}
 	}
 }
+
+func findEmojiStats(t *testing.T, ss store.Store, emojiId string) *model.EmojiStats {
+	result := <-ss.Emoji().GetStats(0, 10000)
+	require.Nil(t, result.Err)
+
+	for _, stats := range result.Data.([]*model.EmojiStats) {
+		if stats.EmojiId == emojiId {
+			return stats
+		}
+	}
+
+	return nil
+}
+
+func testEmojiStats(t *testing.T, ss store.Store) {
+	used := store.Must(ss.Emoji().Save(&model.Emoji{CreatorId: model.NewId(), Name: "a" + model.NewId()})).(*model.Emoji)
+	unused := store.Must(ss.Emoji().Save(&model.Emoji{CreatorId: model.NewId(), Name: "b" + model.NewId()})).(*model.Emoji)
+	defer func() { store.Must(ss.Emoji().Delete(used.Id, model.GetMillis())) }()
+
+	reactions := []*model.Reaction{
+		{UserId: model.NewId(), PostId: model.NewId(), EmojiName: used.Name, CreateAt: 1000},
+		{UserId: model.NewId(), PostId: model.NewId(), EmojiName: used.Name, CreateAt: 3000},
+		{UserId: model.NewId(), PostId: model.NewId(), EmojiName: used.Name, CreateAt: 2000},
+	}
+	for _, reaction := range reactions {
+		store.Must(ss.Reaction().Save(reaction))
+	}
+	defer func() { store.Must(ss.Reaction().DeleteAllWithEmojiName(used.Name)) }()
+
+	// not rolled up yet
+	stats := findEmojiStats(t, ss, used.Id)
+	require.NotNil(t, stats)
+	assert.Equal(t, &model.EmojiStats{EmojiId: used.Id, Name: used.Name}, stats)
+
+	result := <-ss.Emoji().RollUpStats(5000)
+	require.Nil(t, result.Err)
+
+	stats = findEmojiStats(t, ss, used.Id)
+	require.NotNil(t, stats)
+	assert.Equal(t, &model.EmojiStats{EmojiId: used.Id, Name: used.Name, UsageCount: 3, LastUsedAt: 3000, UpdateAt: 5000}, stats)
+
+	stats = findEmojiStats(t, ss, unused.Id)
+	require.NotNil(t, stats)
+	assert.Equal(t, &model.EmojiStats{EmojiId: unused.Id, Name: unused.Name, UpdateAt: 5000}, stats)
+
+	result = <-ss.Emoji().GetStats(0, 10000)
+	require.Nil(t, result.Err)
+	all := result.Data.([]*model.EmojiStats)
+	for i := 1; i < len(all); i++ {
+		assert.True(t, all[i-1].UsageCount >= all[i].UsageCount, "should be sorted by usage")
+	}
+
+	// deleted emoji are dropped from the stats
+	store.Must(ss.Emoji().Delete(unused.Id, model.GetMillis()))
+	assert.Nil(t, findEmojiStats(t, ss, unused.Id))
+
+	result = <-ss.Emoji().RollUpStats(6000)
+	require.Nil(t, result.Err)
+	assert.Nil(t, findEmojiStats(t, ss, unused.Id))
+	assert.Equal(t, int64(6000), findEmojiStats(t, ss, used.Id).UpdateAt)
+}
+
+func testEmojiGetUnused(t *testing.T, ss store.Store) {
+	recentlyUsed := store.Must(ss.Emoji().Save(&model.Emoji{CreatorId: model.NewId(), Name: "c" + model.NewId()})).(*model.Emoji)
+	usedLongAgo := store.Must(ss.Emoji().Save(&model.Emoji{CreatorId: model.NewId(), Name: "d" + model.NewId()})).(*model.Emoji)
+	neverUsed := store.Must(ss.Emoji().Save(&model.Emoji{CreatorId: model.NewId(), Name: "e" + model.NewId()})).(*model.Emoji)
+	defer func() {
+		store.Must(ss.Emoji().Delete(recentlyUsed.Id, model.GetMillis()))
+		store.Must(ss.Emoji().Delete(usedLongAgo.Id, model.GetMillis()))
+		store.Must(ss.Emoji().Delete(neverUsed.Id, model.GetMillis()))
+	}()
+
+	before := model.GetMillis() + 60*1000
+
+	store.Must(ss.Reaction().Save(&model.Reaction{UserId: model.NewId(), PostId: model.NewId(), EmojiName: recentlyUsed.Name, CreateAt: before + 1}))
+	store.Must(ss.Reaction().Save(&model.Reaction{UserId: model.NewId(), PostId: model.NewId(), EmojiName: usedLongAgo.Name, CreateAt: before - 1}))
+	defer func() { store.Must(ss.Reaction().DeleteAllWithEmojiName(recentlyUsed.Name)) }()
+	defer func() { store.Must(ss.Reaction().DeleteAllWithEmojiName(usedLongAgo.Name)) }()
+
+	result := <-ss.Emoji().GetUnused(before)
+	require.Nil(t, result.Err)
+
+	var ids []string
+	for _, emoji := range result.Data.([]*model.Emoji) {
+		ids = append(ids, emoji.Id)
+	}
+	assert.Contains(t, ids, usedLongAgo.Id)
+	assert.Contains(t, ids, neverUsed.Id)
+	assert.NotContains(t, ids, recentlyUsed.Id)
+
+	// emoji created since then aren't included
+	result = <-ss.Emoji().GetUnused(neverUsed.CreateAt)
+	require.Nil(t, result.Err)
+	for _, emoji := range result.Data.([]*model.Emoji) {
+		assert.NotEqual(t, neverUsed.Id, emoji.Id)
+	}
+}
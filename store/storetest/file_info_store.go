@@ -5,8 +5,12 @@ package storetest
 
 import (
 	"fmt"
+	"net/http"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/store"
 )
@@ -14,11 +18,21 @@ import (
 func TestFileInfoStore(t *testing.T, ss store.Store) {
 	t.Run("FileInfoSaveGet", func(t *testing.T) { testFileInfoSaveGet(t, ss) })
 	t.Run("FileInfoSaveGetByPath", func(t *testing.T) { testFileInfoSaveGetByPath(t, ss) })
+	t.Run("FileInfoGetByHash", func(t *testing.T) { testFileInfoGetByHash(t, ss) })
 	t.Run("FileInfoGetForPost", func(t *testing.T) { testFileInfoGetForPost(t, ss) })
+	t.Run("FileInfoGetForPosts", func(t *testing.T) { testFileInfoGetForPosts(t, ss) })
+	t.Run("FileInfoGetForUser", func(t *testing.T) { testFileInfoGetForUser(t, ss) })
 	t.Run("FileInfoAttachToPost", func(t *testing.T) { testFileInfoAttachToPost(t, ss) })
 	t.Run("FileInfoDeleteForPost", func(t *testing.T) { testFileInfoDeleteForPost(t, ss) })
 	t.Run("FileInfoPermanentDelete", func(t *testing.T) { testFileInfoPermanentDelete(t, ss) })
 	t.Run("FileInfoPermanentDeleteBatch", func(t *testing.T) { testFileInfoPermanentDeleteBatch(t, ss) })
+	t.Run("FileInfoPermanentDeleteForChannelBatch", func(t *testing.T) { testFileInfoPermanentDeleteForChannelBatch(t, ss) })
+	t.Run("FileInfoGetBatchAfterId", func(t *testing.T) { testFileInfoGetBatchAfterId(t, ss) })
+	t.Run("FileInfoGetReferencedPaths", func(t *testing.T) { testFileInfoGetReferencedPaths(t, ss) })
+	t.Run("FileInfoSetMissingAt", func(t *testing.T) { testFileInfoSetMissingAt(t, ss) })
+	t.Run("FileInfoSetContentType", func(t *testing.T) { testFileInfoSetContentType(t, ss) })
+	t.Run("FileInfoAnalyticsCount", func(t *testing.T) { testFileInfoAnalyticsCount(t, ss) })
+	t.Run("FileInfoGetWithFilter", func(t *testing.T) { testFileInfoGetWithFilter(t, ss) })
 }
 
 func testFileInfoSaveGet(t *testing.T, ss store.Store) {
@@ -153,6 +167,57 @@ func testFileInfoGetForPost(t *testing.T, ss store.Store) {
 	}
 }
 
+func testFileInfoGetForPosts(t *testing.T, ss store.Store) {
+	userId := model.NewId()
+	postId1 := model.NewId()
+	postId2 := model.NewId()
+
+	infos := []*model.FileInfo{
+		{
+			PostId:    postId1,
+			CreatorId: userId,
+			Path:      "file.txt",
+		},
+		{
+			PostId:    postId2,
+			CreatorId: userId,
+			Path:      "file.txt",
+		},
+		{
+			PostId:    postId2,
+			CreatorId: userId,
+			Path:      "file.txt",
+			DeleteAt:  123,
+		},
+		{
+			PostId:    model.NewId(),
+			CreatorId: userId,
+			Path:      "file.txt",
+		},
+	}
+
+	for i, info := range infos {
+		infos[i] = store.Must(ss.FileInfo().Save(info)).(*model.FileInfo)
+		defer func(id string) {
+			<-ss.FileInfo().PermanentDelete(id)
+		}(infos[i].Id)
+	}
+
+	if result := <-ss.FileInfo().GetForPosts([]string{postId1, postId2}); result.Err != nil {
+		t.Fatal(result.Err)
+	} else if returned := result.Data.([]*model.FileInfo); len(returned) != 2 {
+		t.Fatal("should've returned exactly 2 file infos")
+	} else if returned[0].Id != infos[0].Id || returned[1].Id != infos[1].Id {
+		t.Fatal("should've returned the file infos that haven't been deleted")
+	}
+
+	if result := <-ss.FileInfo().GetForPosts([]string{}); result.Err != nil {
+		t.Fatal(result.Err)
+	} else if returned := result.Data.([]*model.FileInfo); len(returned) != 0 {
+		t.Fatal("shouldn't have returned any file infos")
+	}
+}
+
 func testFileInfoAttachToPost(t *testing.T, ss store.Store) {
 	userId := model.NewId()
 	postId := model.NewId()
@@ -294,3 +359,256 @@ func testFileInfoPermanentDeleteBatch(t *testing.T, ss store.Store) {
 		t.Fatal("Expected 3 fileInfos")
 	}
 }
+
+func testFileInfoPermanentDeleteForChannelBatch(t *testing.T, ss store.Store) {
+	channelId := model.NewId()
+	post := store.Must(ss.Post().Save(&model.Post{
+		ChannelId: channelId,
+		UserId:    model.NewId(),
+		Message:   "test",
+	})).(*model.Post)
+	otherPost := store.Must(ss.Post().Save(&model.Post{
+		ChannelId: model.NewId(),
+		UserId:    model.NewId(),
+		Message:   "test",
+	})).(*model.Post)
+
+	infos := []*model.FileInfo{
+		{CreatorId: model.NewId(), Path: "uploaded.txt", ChannelId: channelId},
+		{CreatorId: model.NewId(), Path: "attached.txt", PostId: post.Id},
+		{CreatorId: model.NewId(), Path: "deleted.txt", PostId: post.Id, DeleteAt: model.GetMillis()},
+	}
+	for i, info := range infos {
+		infos[i] = store.Must(ss.FileInfo().Save(info)).(*model.FileInfo)
+	}
+	other := store.Must(ss.FileInfo().Save(&model.FileInfo{CreatorId: model.NewId(), Path: "other.txt", PostId: otherPost.Id})).(*model.FileInfo)
+	defer ss.FileInfo().PermanentDelete(other.Id)
+
+	var deleted []string
+	for {
+		result := <-ss.FileInfo().PermanentDeleteForChannelBatch(channelId, 2)
+		require.Nil(t, result.Err)
+
+		batch := result.Data.([]*model.FileInfo)
+		require.True(t, len(batch) <= 2)
+		if len(batch) == 0 {
+			break
+		}
+
+		for _, info := range batch {
+			deleted = append(deleted, info.Id)
+		}
+	}
+
+	assert.ElementsMatch(t, []string{infos[0].Id, infos[1].Id, infos[2].Id}, deleted)
+	for _, info := range infos {
+		assert.NotNil(t, (<-ss.FileInfo().Get(info.Id)).Err)
+	}
+	assert.Nil(t, (<-ss.FileInfo().Get(other.Id)).Err)
+}
+
+func testFileInfoGetBatchAfterId(t *testing.T, ss store.Store) {
+	infos := []*model.FileInfo{
+		{CreatorId: model.NewId(), Path: "first.txt"},
+		{CreatorId: model.NewId(), Path: "second.txt"},
+		{CreatorId: model.NewId(), Path: "deleted.txt", DeleteAt: model.GetMillis()},
+	}
+	expected := make([]string, len(infos))
+	for i, info := range infos {
+		infos[i] = store.Must(ss.FileInfo().Save(info)).(*model.FileInfo)
+		defer ss.FileInfo().PermanentDelete(infos[i].Id)
+		expected[i] = infos[i].Id
+	}
+
+	var paged []string
+	afterId := ""
+	for {
+		result := <-ss.FileInfo().GetBatchAfterId(afterId, 2)
+		require.Nil(t, result.Err)
+
+		batch := result.Data.([]*model.FileInfo)
+		require.True(t, len(batch) <= 2)
+		if len(batch) == 0 {
+			break
+		}
+
+		for _, info := range batch {
+			assert.True(t, info.Id > afterId, "infos should be in order of id")
+			afterId = info.Id
+			paged = append(paged, info.Id)
+		}
+	}
+
+	assert.Subset(t, paged, expected, "every info, including deleted ones, should have been paged through")
+}
+
+func testFileInfoGetReferencedPaths(t *testing.T, ss store.Store) {
+	prefix := model.NewId() + "/"
+	infos := []*model.FileInfo{
+		{CreatorId: model.NewId(), Path: prefix + "image.png", ThumbnailPath: prefix + "image_thumb.jpg", PreviewPath: prefix + "image_preview.jpg"},
+		{CreatorId: model.NewId(), Path: prefix + "deleted.txt", DeleteAt: model.GetMillis()},
+	}
+	for _, info := range infos {
+		info = store.Must(ss.FileInfo().Save(info)).(*model.FileInfo)
+		defer ss.FileInfo().PermanentDelete(info.Id)
+	}
+
+	result := <-ss.FileInfo().GetReferencedPaths([]string{prefix + "image.png", prefix + "image_thumb.jpg", prefix + "image_preview.jpg", prefix + "deleted.txt", prefix + "dangling.txt"})
+	require.Nil(t, result.Err)
+	assert.ElementsMatch(t, []string{prefix + "image.png", prefix + "image_thumb.jpg", prefix + "image_preview.jpg", prefix + "deleted.txt"}, result.Data.([]string))
+
+	result = <-ss.FileInfo().GetReferencedPaths([]string{})
+	require.Nil(t, result.Err)
+	assert.Empty(t, result.Data.([]string))
+}
+
+func testFileInfoSetMissingAt(t *testing.T, ss store.Store) {
+	missing := store.Must(ss.FileInfo().Save(&model.FileInfo{CreatorId: model.NewId(), Path: "missing.txt"})).(*model.FileInfo)
+	defer ss.FileInfo().PermanentDelete(missing.Id)
+	other := store.Must(ss.FileInfo().Save(&model.FileInfo{CreatorId: model.NewId(), Path: "other.txt"})).(*model.FileInfo)
+	defer ss.FileInfo().PermanentDelete(other.Id)
+
+	require.Nil(t, (<-ss.FileInfo().SetMissingAt([]string{missing.Id}, 1234)).Err)
+	assert.EqualValues(t, 1234, store.Must(ss.FileInfo().Get(missing.Id)).(*model.FileInfo).MissingAt)
+	assert.EqualValues(t, 0, store.Must(ss.FileInfo().Get(other.Id)).(*model.FileInfo).MissingAt)
+
+	require.Nil(t, (<-ss.FileInfo().SetMissingAt([]string{missing.Id}, 0)).Err)
+	assert.EqualValues(t, 0, store.Must(ss.FileInfo().Get(missing.Id)).(*model.FileInfo).MissingAt)
+
+	require.Nil(t, (<-ss.FileInfo().SetMissingAt([]string{}, 1234)).Err)
+}
+
+func testFileInfoSetContentType(t *testing.T, ss store.Store) {
+	info := store.Must(ss.FileInfo().Save(&model.FileInfo{CreatorId: model.NewId(), Path: "file.png", MimeType: "image/png"})).(*model.FileInfo)
+	defer ss.FileInfo().PermanentDelete(info.Id)
+	assert.Equal(t, "", store.Must(ss.FileInfo().Get(info.Id)).(*model.FileInfo).ContentType)
+
+	require.Nil(t, (<-ss.FileInfo().SetContentType(info.Id, "text/html; charset=utf-8")).Err)
+	saved := store.Must(ss.FileInfo().Get(info.Id)).(*model.FileInfo)
+	assert.Equal(t, "text/html; charset=utf-8", saved.ContentType)
+	assert.Equal(t, "image/png", saved.MimeType)
+}
+
+func testFileInfoGetByHash(t *testing.T, ss store.Store) {
+	userId := model.NewId()
+	otherUserId := model.NewId()
+	hash := model.NewId() + model.NewId()
+
+	first := store.Must(ss.FileInfo().Save(&model.FileInfo{CreatorId: otherUserId, Path: "first.pdf", Size: 100, Hash: hash, CreateAt: 1000})).(*model.FileInfo)
+	defer ss.FileInfo().PermanentDelete(first.Id)
+	own := store.Must(ss.FileInfo().Save(&model.FileInfo{CreatorId: userId, Path: "own.pdf", Size: 100, Hash: hash, CreateAt: 2000, DeleteAt: 3000})).(*model.FileInfo)
+	defer ss.FileInfo().PermanentDelete(own.Id)
+	missing := store.Must(ss.FileInfo().Save(&model.FileInfo{CreatorId: userId, Path: "missing.pdf", Size: 100, Hash: hash, CreateAt: 500, MissingAt: 4000})).(*model.FileInfo)
+	defer ss.FileInfo().PermanentDelete(missing.Id)
+
+	found := store.Must(ss.FileInfo().GetByHash(hash, 100, "")).(*model.FileInfo)
+	assert.Equal(t, first.Id, found.Id)
+
+	found = store.Must(ss.FileInfo().GetByHash(hash, 100, userId)).(*model.FileInfo)
+	assert.Equal(t, own.Id, found.Id, "deleted files are still stored")
+
+	result := <-ss.FileInfo().GetByHash(hash, 101, "")
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+
+	result = <-ss.FileInfo().GetByHash(hash, 100, model.NewId())
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+}
+
+func testFileInfoGetForUser(t *testing.T, ss store.Store) {
+	userId := model.NewId()
+
+	infos := []*model.FileInfo{
+		{CreatorId: userId, Path: "file1.txt"},
+		{CreatorId: userId, Path: "file2.txt"},
+		{CreatorId: userId, Path: "file3.txt", DeleteAt: model.GetMillis()},
+		{CreatorId: model.NewId(), Path: "file4.txt"},
+	}
+	for i, info := range infos {
+		infos[i] = store.Must(ss.FileInfo().Save(info)).(*model.FileInfo)
+		defer ss.FileInfo().PermanentDelete(infos[i].Id)
+	}
+
+	if result := <-ss.FileInfo().GetForUser(userId); result.Err != nil {
+		t.Fatal(result.Err)
+	} else if returned := result.Data.([]*model.FileInfo); len(returned) != 2 {
+		t.Fatal("should've returned the 2 undeleted files of the user")
+	} else if returned[0].Id != infos[0].Id || returned[1].Id != infos[1].Id {
+		t.Fatal("should've returned the files of the user in order")
+	}
+}
+
+func testFileInfoAnalyticsCount(t *testing.T, ss store.Store) {
+	startCount := store.Must(ss.FileInfo().AnalyticsCount()).(int64)
+
+	infos := []*model.FileInfo{
+		{CreatorId: model.NewId(), Path: "file1.txt"},
+		{CreatorId: model.NewId(), Path: "file2.txt"},
+		{CreatorId: model.NewId(), Path: "file3.txt", DeleteAt: model.GetMillis()},
+	}
+	for i, info := range infos {
+		infos[i] = store.Must(ss.FileInfo().Save(info)).(*model.FileInfo)
+		defer ss.FileInfo().PermanentDelete(infos[i].Id)
+	}
+
+	if result := <-ss.FileInfo().AnalyticsCount(); result.Err != nil {
+		t.Fatal(result.Err)
+	} else if count := result.Data.(int64); count != startCount+2 {
+		t.Fatalf("should've counted the 2 undeleted files, got %v", count-startCount)
+	}
+}
+
+func testFileInfoGetWithFilter(t *testing.T, ss store.Store) {
+	userId := model.NewId()
+	teamId := model.NewId()
+
+	channel1 := store.Must(ss.Channel().Save(&model.Channel{TeamId: teamId, DisplayName: "Channel1", Name: "zz" + model.NewId() + "b", Type: model.CHANNEL_OPEN}, -1)).(*model.Channel)
+	channel2 := store.Must(ss.Channel().Save(&model.Channel{TeamId: model.NewId(), DisplayName: "Channel2", Name: "zz" + model.NewId() + "b", Type: model.CHANNEL_OPEN}, -1)).(*model.Channel)
+	post1 := store.Must(ss.Post().Save(&model.Post{ChannelId: channel1.Id, UserId: userId, Message: "message"})).(*model.Post)
+	post2 := store.Must(ss.Post().Save(&model.Post{ChannelId: channel2.Id, UserId: userId, Message: "message"})).(*model.Post)
+
+	infos := []*model.FileInfo{
+		{CreatorId: userId, PostId: post1.Id, Path: "file1.txt", Size: 10, CreateAt: 1000},
+		{CreatorId: userId, PostId: post1.Id, Path: "file2.txt", Size: 20, CreateAt: 2000},
+		{CreatorId: userId, PostId: post2.Id, Path: "file3.txt", Size: 40, CreateAt: 3000},
+		{CreatorId: userId, Path: "file4.txt", Size: 80, CreateAt: 4000},
+		{CreatorId: userId, Path: "file5.txt", Size: 160, CreateAt: 5000, DeleteAt: 5000},
+		{CreatorId: model.NewId(), PostId: post1.Id, Path: "file6.txt", Size: 320, CreateAt: 6000},
+	}
+	for i, info := range infos {
+		infos[i] = store.Must(ss.FileInfo().Save(info)).(*model.FileInfo)
+		defer ss.FileInfo().PermanentDelete(infos[i].Id)
+	}
+
+	for name, tc := range map[string]struct {
+		Filter model.FileInfoFilter
+		Ids    []string
+		Size   int64
+	}{
+		"all":                    {model.FileInfoFilter{CreatorId: userId}, []string{infos[3].Id, infos[2].Id, infos[1].Id, infos[0].Id}, 150},
+		"team":                   {model.FileInfoFilter{CreatorId: userId, TeamId: teamId}, []string{infos[1].Id, infos[0].Id}, 30},
+		"channel":                {model.FileInfoFilter{CreatorId: userId, ChannelId: channel2.Id}, []string{infos[2].Id}, 40},
+		"team and other channel": {model.FileInfoFilter{CreatorId: userId, TeamId: teamId, ChannelId: channel2.Id}, []string{}, 0},
+		"dates":                  {model.FileInfoFilter{CreatorId: userId, CreatedAfter: 2000, CreatedBefore: 4000}, []string{infos[2].Id, infos[1].Id}, 60},
+	} {
+		t.Run(name, func(t *testing.T) {
+			returned := store.Must(ss.FileInfo().GetWithFilter(&tc.Filter, 0, 100)).([]*model.FileInfo)
+			ids := []string{}
+			for _, info := range returned {
+				ids = append(ids, info.Id)
+			}
+			assert.Equal(t, tc.Ids, ids)
+
+			stats := store.Must(ss.FileInfo().GetStatsWithFilter(&tc.Filter)).(*model.FileInfoStats)
+			assert.Equal(t, int64(len(tc.Ids)), stats.TotalCount)
+			assert.Equal(t, tc.Size, stats.TotalSize)
+		})
+	}
+
+	// Pages are taken from the newest first
+	page := store.Must(ss.FileInfo().GetWithFilter(&model.FileInfoFilter{CreatorId: userId}, 1, 2)).([]*model.FileInfo)
+	require.Len(t, page, 2)
+	assert.Equal(t, infos[2].Id, page[0].Id)
+	assert.Equal(t, infos[1].Id, page[1].Id)
+}
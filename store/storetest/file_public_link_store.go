@@ -0,0 +1,152 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package storetest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func TestFilePublicLinkStore(t *testing.T, ss store.Store) {
+	t.Run("SaveAndGet", func(t *testing.T) { testFilePublicLinkStoreSaveAndGet(t, ss) })
+	t.Run("GetActiveForUser", func(t *testing.T) { testFilePublicLinkStoreGetActiveForUser(t, ss) })
+	t.Run("GetActiveForChannel", func(t *testing.T) { testFilePublicLinkStoreGetActiveForChannel(t, ss) })
+	t.Run("CountDownload", func(t *testing.T) { testFilePublicLinkStoreCountDownload(t, ss) })
+	t.Run("CountDownloadExpired", func(t *testing.T) { testFilePublicLinkStoreCountDownloadExpired(t, ss) })
+	t.Run("CountDownloadRevoked", func(t *testing.T) { testFilePublicLinkStoreCountDownloadRevoked(t, ss) })
+}
+
+func newFilePublicLink(creatorId string) *model.FilePublicLink {
+	return &model.FilePublicLink{FileId: model.NewId(), ChannelId: model.NewId(), CreatorId: creatorId}
+}
+
+func testFilePublicLinkStoreSaveAndGet(t *testing.T, ss store.Store) {
+	link := newFilePublicLink(model.NewId())
+	link.MaxDownloads = 3
+	link = store.Must(ss.FilePublicLink().Save(link)).(*model.FilePublicLink)
+	assert.Len(t, link.Id, 26)
+
+	result := <-ss.FilePublicLink().Get(link.Id)
+	require.Nil(t, result.Err)
+	assert.Equal(t, link, result.Data.(*model.FilePublicLink))
+
+	result = <-ss.FilePublicLink().Get(model.NewId())
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+
+	invalid := newFilePublicLink(model.NewId())
+	invalid.MaxDownloads = -1
+	result = <-ss.FilePublicLink().Save(invalid)
+	assert.NotNil(t, result.Err)
+}
+
+func testFilePublicLinkStoreGetActiveForUser(t *testing.T, ss store.Store) {
+	userId := model.NewId()
+	now := model.GetMillis()
+
+	active := store.Must(ss.FilePublicLink().Save(newFilePublicLink(userId))).(*model.FilePublicLink)
+
+	expiring := newFilePublicLink(userId)
+	expiring.ExpiresAt = now + 60*1000
+	store.Must(ss.FilePublicLink().Save(expiring))
+
+	usedUp := newFilePublicLink(userId)
+	usedUp.MaxDownloads = 1
+	store.Must(ss.FilePublicLink().Save(usedUp))
+	store.Must(ss.FilePublicLink().CountDownload(usedUp.Id, now))
+
+	revoked := store.Must(ss.FilePublicLink().Save(newFilePublicLink(userId))).(*model.FilePublicLink)
+	store.Must(ss.FilePublicLink().Revoke(revoked.Id, now))
+
+	store.Must(ss.FilePublicLink().Save(newFilePublicLink(model.NewId())))
+
+	links := store.Must(ss.FilePublicLink().GetActiveForUser(userId, now)).([]*model.FilePublicLink)
+	ids := []string{}
+	for _, link := range links {
+		ids = append(ids, link.Id)
+	}
+	assert.ElementsMatch(t, []string{active.Id, expiring.Id}, ids)
+
+	links = store.Must(ss.FilePublicLink().GetActiveForUser(userId, expiring.ExpiresAt)).([]*model.FilePublicLink)
+	require.Len(t, links, 1)
+	assert.Equal(t, active.Id, links[0].Id)
+}
+
+func testFilePublicLinkStoreGetActiveForChannel(t *testing.T, ss store.Store) {
+	channelId := model.NewId()
+	now := model.GetMillis()
+
+	newChannelLink := func() *model.FilePublicLink {
+		link := newFilePublicLink(model.NewId())
+		link.ChannelId = channelId
+		return link
+	}
+
+	active := store.Must(ss.FilePublicLink().Save(newChannelLink())).(*model.FilePublicLink)
+
+	expired := newChannelLink()
+	expired.ExpiresAt = now + 60*1000
+	store.Must(ss.FilePublicLink().Save(expired))
+
+	revoked := store.Must(ss.FilePublicLink().Save(newChannelLink())).(*model.FilePublicLink)
+	store.Must(ss.FilePublicLink().Revoke(revoked.Id, now))
+
+	store.Must(ss.FilePublicLink().Save(newFilePublicLink(model.NewId())))
+
+	links := store.Must(ss.FilePublicLink().GetActiveForChannel(channelId, expired.ExpiresAt)).([]*model.FilePublicLink)
+	require.Len(t, links, 1)
+	assert.Equal(t, active.Id, links[0].Id)
+}
+
+func testFilePublicLinkStoreCountDownload(t *testing.T, ss store.Store) {
+	link := newFilePublicLink(model.NewId())
+	link.MaxDownloads = 2
+	store.Must(ss.FilePublicLink().Save(link))
+	now := model.GetMillis()
+
+	store.Must(ss.FilePublicLink().CountDownload(link.Id, now))
+	store.Must(ss.FilePublicLink().CountDownload(link.Id, now))
+
+	result := <-ss.FilePublicLink().CountDownload(link.Id, now)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "store.sql_file_public_link.count_download.unavailable.app_error", result.Err.Id)
+
+	link = store.Must(ss.FilePublicLink().Get(link.Id)).(*model.FilePublicLink)
+	assert.Equal(t, 2, link.DownloadCount)
+
+	result = <-ss.FilePublicLink().CountDownload(model.NewId(), now)
+	assert.NotNil(t, result.Err)
+}
+
+func testFilePublicLinkStoreCountDownloadExpired(t *testing.T, ss store.Store) {
+	link := newFilePublicLink(model.NewId())
+	link.ExpiresAt = model.GetMillis() + 60*1000
+	store.Must(ss.FilePublicLink().Save(link))
+
+	store.Must(ss.FilePublicLink().CountDownload(link.Id, link.ExpiresAt-1))
+
+	result := <-ss.FilePublicLink().CountDownload(link.Id, link.ExpiresAt)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "store.sql_file_public_link.count_download.unavailable.app_error", result.Err.Id)
+}
+
+func testFilePublicLinkStoreCountDownloadRevoked(t *testing.T, ss store.Store) {
+	link := store.Must(ss.FilePublicLink().Save(newFilePublicLink(model.NewId()))).(*model.FilePublicLink)
+	now := model.GetMillis()
+
+	store.Must(ss.FilePublicLink().Revoke(link.Id, now))
+
+	result := <-ss.FilePublicLink().CountDownload(link.Id, now)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "store.sql_file_public_link.count_download.unavailable.app_error", result.Err.Id)
+
+	link = store.Must(ss.FilePublicLink().Get(link.Id)).(*model.FilePublicLink)
+	assert.Equal(t, now, link.DeleteAt)
+}
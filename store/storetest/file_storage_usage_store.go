@@ -0,0 +1,204 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package storetest
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func TestFileStorageUsageStore(t *testing.T, ss store.Store) {
+	t.Run("ReserveAndRelease", func(t *testing.T) { testFileStorageUsageStoreReserveAndRelease(t, ss) })
+	t.Run("ReserveConcurrently", func(t *testing.T) { testFileStorageUsageStoreReserveConcurrently(t, ss) })
+	t.Run("Exempt", func(t *testing.T) { testFileStorageUsageStoreExempt(t, ss) })
+	t.Run("GetTop", func(t *testing.T) { testFileStorageUsageStoreGetTop(t, ss) })
+	t.Run("Reconcile", func(t *testing.T) { testFileStorageUsageStoreReconcile(t, ss) })
+}
+
+func getFileStorageUsageBytes(t *testing.T, ss store.Store, id string) int64 {
+	result := <-ss.FileStorageUsage().Get(id)
+	require.Nil(t, result.Err)
+	return result.Data.(*model.FileStorageUsage).Bytes
+}
+
+func testFileStorageUsageStoreReserveAndRelease(t *testing.T, ss store.Store) {
+	userId := model.NewId()
+	teamId := model.NewId()
+
+	result := <-ss.FileStorageUsage().Get(userId)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+
+	store.Must(ss.FileStorageUsage().Reserve(userId, teamId, 60, 100, 1000))
+	assert.Equal(t, int64(60), getFileStorageUsageBytes(t, ss, userId))
+	assert.Equal(t, int64(60), getFileStorageUsageBytes(t, ss, teamId))
+
+	result = <-ss.FileStorageUsage().Reserve(userId, teamId, 50, 100, 1000)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "store.sql_file_storage_usage.reserve.user_quota_exceeded.app_error", result.Err.Id)
+	assert.Equal(t, int64(60), getFileStorageUsageBytes(t, ss, userId))
+	assert.Equal(t, int64(60), getFileStorageUsageBytes(t, ss, teamId))
+
+	// Going over the team's quota leaves the user's usage as it was
+	result = <-ss.FileStorageUsage().Reserve(userId, teamId, 40, 100, 80)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "store.sql_file_storage_usage.reserve.team_quota_exceeded.app_error", result.Err.Id)
+	assert.Equal(t, int64(60), getFileStorageUsageBytes(t, ss, userId))
+	assert.Equal(t, int64(60), getFileStorageUsageBytes(t, ss, teamId))
+
+	store.Must(ss.FileStorageUsage().Reserve(userId, teamId, 40, 100, 0))
+	assert.Equal(t, int64(100), getFileStorageUsageBytes(t, ss, userId))
+	assert.Equal(t, int64(100), getFileStorageUsageBytes(t, ss, teamId))
+
+	store.Must(ss.FileStorageUsage().Reserve(userId, "", 1000, 0, 0))
+	assert.Equal(t, int64(1100), getFileStorageUsageBytes(t, ss, userId))
+	assert.Equal(t, int64(100), getFileStorageUsageBytes(t, ss, teamId))
+
+	store.Must(ss.FileStorageUsage().Release(userId, teamId, 1000))
+	assert.Equal(t, int64(100), getFileStorageUsageBytes(t, ss, userId))
+	assert.Equal(t, int64(0), getFileStorageUsageBytes(t, ss, teamId), "usage shouldn't go below 0")
+}
+
+func testFileStorageUsageStoreReserveConcurrently(t *testing.T, ss store.Store) {
+	userId := model.NewId()
+	teamId := model.NewId()
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	reserved := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if result := <-ss.FileStorageUsage().Reserve(userId, teamId, 10, 55, 0); result.Err == nil {
+				lock.Lock()
+				reserved++
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 5, reserved, "only the uploads that fit in the quota should get through")
+	assert.Equal(t, int64(50), getFileStorageUsageBytes(t, ss, userId))
+	assert.Equal(t, int64(50), getFileStorageUsageBytes(t, ss, teamId))
+}
+
+func testFileStorageUsageStoreExempt(t *testing.T, ss store.Store) {
+	userId := model.NewId()
+	teamId := model.NewId()
+
+	store.Must(ss.FileStorageUsage().SetExempt(userId, true))
+	store.Must(ss.FileStorageUsage().Reserve(userId, teamId, 500, 100, 100))
+	assert.Equal(t, int64(500), getFileStorageUsageBytes(t, ss, userId), "exempt users should still be counted")
+	assert.Equal(t, int64(500), getFileStorageUsageBytes(t, ss, teamId))
+
+	store.Must(ss.FileStorageUsage().SetExempt(userId, false))
+	result := <-ss.FileStorageUsage().Reserve(userId, teamId, 1, 100, 0)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "store.sql_file_storage_usage.reserve.user_quota_exceeded.app_error", result.Err.Id)
+
+	result = <-ss.FileStorageUsage().Get(userId)
+	require.Nil(t, result.Err)
+	assert.False(t, result.Data.(*model.FileStorageUsage).Exempt)
+}
+
+func testFileStorageUsageStoreGetTop(t *testing.T, ss store.Store) {
+	// Bigger than any other test's usage, so that these come first
+	bigger := model.NewId()
+	biggest := model.NewId()
+	store.Must(ss.FileStorageUsage().Reserve(bigger, "", 1<<40, 0, 0))
+	store.Must(ss.FileStorageUsage().Reserve(biggest, "", 1<<41, 0, 0))
+
+	result := <-ss.FileStorageUsage().GetTop(model.FILE_STORAGE_USAGE_TYPE_USER, 0, 2)
+	require.Nil(t, result.Err)
+	usages := result.Data.([]*model.FileStorageUsage)
+	require.Len(t, usages, 2)
+	assert.Equal(t, biggest, usages[0].Id)
+	assert.Equal(t, bigger, usages[1].Id)
+	assert.Equal(t, model.FILE_STORAGE_USAGE_TYPE_USER, usages[0].Type)
+
+	result = <-ss.FileStorageUsage().GetTop(model.FILE_STORAGE_USAGE_TYPE_USER, 1, 1)
+	require.Nil(t, result.Err)
+	usages = result.Data.([]*model.FileStorageUsage)
+	require.Len(t, usages, 1)
+	assert.Equal(t, bigger, usages[0].Id)
+
+	result = <-ss.FileStorageUsage().GetTop(model.FILE_STORAGE_USAGE_TYPE_TEAM, 0, 100)
+	require.Nil(t, result.Err)
+	for _, usage := range result.Data.([]*model.FileStorageUsage) {
+		assert.NotEqual(t, biggest, usage.Id, "users shouldn't be listed with teams")
+	}
+
+	store.Must(ss.FileStorageUsage().Release(bigger, "", 1<<40))
+	store.Must(ss.FileStorageUsage().Release(biggest, "", 1<<41))
+}
+
+func reconcileFileStorageUsage(t *testing.T, ss store.Store, usageType string) int64 {
+	var corrected int64
+	afterId := ""
+	for {
+		result := <-ss.FileStorageUsage().Reconcile(usageType, afterId, 1000)
+		require.Nil(t, result.Err)
+
+		batch := result.Data.(*model.FileStorageUsageBatch)
+		corrected += batch.Corrected
+		if batch.LastId == "" {
+			return corrected
+		}
+		afterId = batch.LastId
+	}
+}
+
+func testFileStorageUsageStoreReconcile(t *testing.T, ss store.Store) {
+	team := store.Must(ss.Team().Save(&model.Team{
+		DisplayName: "Team",
+		Name:        "zz" + model.NewId(),
+		Email:       model.NewId() + "@nowhere.com",
+		Type:        model.TEAM_OPEN,
+	})).(*model.Team)
+	channel := store.Must(ss.Channel().Save(&model.Channel{
+		TeamId:      team.Id,
+		DisplayName: "Channel",
+		Name:        "zz" + model.NewId() + "b",
+		Type:        model.CHANNEL_OPEN,
+	}, -1)).(*model.Channel)
+	user := store.Must(ss.User().Save(&model.User{Email: model.NewId()})).(*model.User)
+
+	post := store.Must(ss.Post().Save(&model.Post{ChannelId: channel.Id, UserId: user.Id, Message: "files"})).(*model.Post)
+
+	saveFile := func(info *model.FileInfo) {
+		info.CreatorId = user.Id
+		info.Path = "file.txt"
+		store.Must(ss.FileInfo().Save(info))
+	}
+	saveFile(&model.FileInfo{ChannelId: channel.Id, Size: 100})
+	// Files from before they had a channel are counted where they were posted
+	saveFile(&model.FileInfo{PostId: post.Id, Size: 20})
+	saveFile(&model.FileInfo{ChannelId: channel.Id, Size: 1000, DeleteAt: model.GetMillis()})
+
+	// The usage drifts, such as if the server stopped part way through an upload
+	store.Must(ss.FileStorageUsage().Reserve(user.Id, team.Id, 5000, 0, 0))
+
+	assert.True(t, reconcileFileStorageUsage(t, ss, model.FILE_STORAGE_USAGE_TYPE_USER) >= 1)
+	assert.True(t, reconcileFileStorageUsage(t, ss, model.FILE_STORAGE_USAGE_TYPE_TEAM) >= 1)
+	assert.Equal(t, int64(120), getFileStorageUsageBytes(t, ss, user.Id))
+	assert.Equal(t, int64(120), getFileStorageUsageBytes(t, ss, team.Id))
+
+	// Users and teams without a usage are given one
+	other := store.Must(ss.User().Save(&model.User{Email: model.NewId()})).(*model.User)
+	store.Must(ss.FileInfo().Save(&model.FileInfo{CreatorId: other.Id, Path: "file.txt", Size: 7}))
+
+	reconcileFileStorageUsage(t, ss, model.FILE_STORAGE_USAGE_TYPE_USER)
+	assert.Equal(t, int64(7), getFileStorageUsageBytes(t, ss, other.Id))
+	assert.Equal(t, int64(120), getFileStorageUsageBytes(t, ss, user.Id), "usage that's right should be left alone")
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package storetest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func TestInviteLinkStore(t *testing.T, ss store.Store) {
+	t.Run("SaveAndGet", func(t *testing.T) { testInviteLinkStoreSaveAndGet(t, ss) })
+	t.Run("GetForTeam", func(t *testing.T) { testInviteLinkStoreGetForTeam(t, ss) })
+	t.Run("Redeem", func(t *testing.T) { testInviteLinkStoreRedeem(t, ss) })
+	t.Run("RedeemExpired", func(t *testing.T) { testInviteLinkStoreRedeemExpired(t, ss) })
+	t.Run("RedeemRevoked", func(t *testing.T) { testInviteLinkStoreRedeemRevoked(t, ss) })
+}
+
+func testInviteLinkStoreSaveAndGet(t *testing.T, ss store.Store) {
+	link := store.Must(ss.InviteLink().Save(&model.InviteLink{TeamId: model.NewId(), CreatorId: model.NewId(), MaxUses: 3, GuestOnly: true})).(*model.InviteLink)
+	assert.Len(t, link.Id, 26)
+
+	result := <-ss.InviteLink().Get(link.Id)
+	require.Nil(t, result.Err)
+	assert.Equal(t, link, result.Data.(*model.InviteLink))
+
+	result = <-ss.InviteLink().Get(model.NewId())
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+
+	result = <-ss.InviteLink().Save(&model.InviteLink{TeamId: model.NewId(), CreatorId: model.NewId(), MaxUses: -1})
+	assert.NotNil(t, result.Err)
+}
+
+func testInviteLinkStoreGetForTeam(t *testing.T, ss store.Store) {
+	teamId := model.NewId()
+
+	link1 := store.Must(ss.InviteLink().Save(&model.InviteLink{TeamId: teamId, CreatorId: model.NewId()})).(*model.InviteLink)
+	link2 := &model.InviteLink{TeamId: teamId, CreatorId: model.NewId()}
+	store.Must(ss.InviteLink().Save(link2))
+	store.Must(ss.InviteLink().Revoke(link2.Id, model.GetMillis()))
+	store.Must(ss.InviteLink().Save(&model.InviteLink{TeamId: model.NewId(), CreatorId: model.NewId()}))
+
+	links := store.Must(ss.InviteLink().GetForTeam(teamId)).([]*model.InviteLink)
+	require.Len(t, links, 2)
+
+	ids := []string{links[0].Id, links[1].Id}
+	assert.ElementsMatch(t, []string{link1.Id, link2.Id}, ids)
+
+	for _, link := range links {
+		if link.Id == link2.Id {
+			assert.NotEqual(t, int64(0), link.DeleteAt)
+		}
+	}
+}
+
+func testInviteLinkStoreRedeem(t *testing.T, ss store.Store) {
+	link := store.Must(ss.InviteLink().Save(&model.InviteLink{TeamId: model.NewId(), CreatorId: model.NewId(), MaxUses: 2})).(*model.InviteLink)
+
+	userId1 := model.NewId()
+	userId2 := model.NewId()
+	now := model.GetMillis()
+
+	redemption := store.Must(ss.InviteLink().Redeem(link.Id, userId1, now)).(*model.InviteLinkRedemption)
+	assert.Equal(t, link.Id, redemption.LinkId)
+	assert.Equal(t, userId1, redemption.UserId)
+
+	result := <-ss.InviteLink().Redeem(link.Id, userId1, now+1)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "store.sql_invite_link.redeem.exists.app_error", result.Err.Id)
+
+	store.Must(ss.InviteLink().Redeem(link.Id, userId2, now+2))
+
+	result = <-ss.InviteLink().Redeem(link.Id, model.NewId(), now+3)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "store.sql_invite_link.redeem.unavailable.app_error", result.Err.Id)
+
+	link = store.Must(ss.InviteLink().Get(link.Id)).(*model.InviteLink)
+	assert.Equal(t, 2, link.Uses)
+
+	redemptions := store.Must(ss.InviteLink().GetRedemptions(link.Id)).([]*model.InviteLinkRedemption)
+	require.Len(t, redemptions, 2)
+	assert.Equal(t, userId1, redemptions[0].UserId)
+	assert.Equal(t, userId2, redemptions[1].UserId)
+}
+
+func testInviteLinkStoreRedeemExpired(t *testing.T, ss store.Store) {
+	link := store.Must(ss.InviteLink().Save(&model.InviteLink{TeamId: model.NewId(), CreatorId: model.NewId(), ExpiresAt: model.GetMillis() + 1000})).(*model.InviteLink)
+
+	result := <-ss.InviteLink().Redeem(link.Id, model.NewId(), link.ExpiresAt)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "store.sql_invite_link.redeem.unavailable.app_error", result.Err.Id)
+
+	store.Must(ss.InviteLink().Redeem(link.Id, model.NewId(), link.ExpiresAt-1))
+}
+
+func testInviteLinkStoreRedeemRevoked(t *testing.T, ss store.Store) {
+	link := store.Must(ss.InviteLink().Save(&model.InviteLink{TeamId: model.NewId(), CreatorId: model.NewId()})).(*model.InviteLink)
+
+	store.Must(ss.InviteLink().Redeem(link.Id, model.NewId(), model.GetMillis()))
+	store.Must(ss.InviteLink().Revoke(link.Id, model.GetMillis()))
+
+	result := <-ss.InviteLink().Redeem(link.Id, model.NewId(), model.GetMillis())
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "store.sql_invite_link.redeem.unavailable.app_error", result.Err.Id)
+
+	result = <-ss.InviteLink().Redeem(model.NewId(), model.NewId(), model.GetMillis())
+	assert.NotNil(t, result.Err)
+}
@@ -24,6 +24,7 @@ func TestJobStore(t *testing.T, ss store.Store) {
 	t.Run("JobUpdateOptimistically", func(t *testing.T) { testJobUpdateOptimistically(t, ss) })
 	t.Run("JobUpdateStatusUpdateStatusOptimistically", func(t *testing.T) { testJobUpdateStatusUpdateStatusOptimistically(t, ss) })
 	t.Run("JobDelete", func(t *testing.T) { testJobDelete(t, ss) })
+	t.Run("PermanentDeleteFinishedBatch", func(t *testing.T) { testJobPermanentDeleteFinishedBatch(t, ss) })
 }
 
 func testJobSaveGet(t *testing.T, ss store.Store) {
@@ -505,3 +506,42 @@ func testJobDelete(t *testing.T, ss store.Store) {
 		t.Fatal(result.Err)
 	}
 }
+
+func testJobPermanentDeleteFinishedBatch(t *testing.T, ss store.Store) {
+	saveJob := func(status string, createAt int64) *model.Job {
+		return store.Must(ss.Job().Save(&model.Job{
+			Id:       model.NewId(),
+			Type:     model.NewId(),
+			Status:   status,
+			CreateAt: createAt,
+		})).(*model.Job)
+	}
+
+	// Created long before any other jobs so that they're the only ones trimmed
+	pending := saveJob(model.JOB_STATUS_PENDING, 1)
+	inProgress := saveJob(model.JOB_STATUS_IN_PROGRESS, 2)
+	cancelRequested := saveJob(model.JOB_STATUS_CANCEL_REQUESTED, 3)
+	success := saveJob(model.JOB_STATUS_SUCCESS, 4)
+	failed := saveJob(model.JOB_STATUS_ERROR, 5)
+
+	// The newest finished job is always kept for a maximum of one row
+	newest := saveJob(model.JOB_STATUS_CANCELED, model.GetMillis()+60*60*1000)
+	assert.Equal(t, newest.CreateAt, store.Must(ss.Job().GetFinishedMaxRowsCutoff(1)).(int64))
+
+	assert.Equal(t, int64(2), store.Must(ss.Job().GetFinishedCountBefore(6)).(int64))
+
+	assert.Equal(t, int64(1), store.Must(ss.Job().PermanentDeleteFinishedBatch(6, 1)).(int64))
+	assert.Equal(t, int64(1), store.Must(ss.Job().PermanentDeleteFinishedBatch(6, 1)).(int64))
+	assert.Equal(t, int64(0), store.Must(ss.Job().PermanentDeleteFinishedBatch(6, 1)).(int64))
+
+	for _, job := range []*model.Job{success, failed} {
+		result := <-ss.Job().Get(job.Id)
+		assert.NotNil(t, result.Err, "finished jobs should be deleted")
+	}
+
+	for _, job := range []*model.Job{pending, inProgress, cancelRequested, newest} {
+		result := <-ss.Job().Get(job.Id)
+		assert.Nil(t, result.Err, "jobs that haven't finished should be kept")
+		store.Must(ss.Job().Delete(job.Id))
+	}
+}
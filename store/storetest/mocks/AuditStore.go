@@ -29,6 +29,38 @@ func (_m *AuditStore) Get(user_id string, offset int, limit int) store.StoreChan
 	return r0
 }
 
+// GetCountBefore provides a mock function with given fields: endTime
+func (_m *AuditStore) GetCountBefore(endTime int64) store.StoreChannel {
+	ret := _m.Called(endTime)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(int64) store.StoreChannel); ok {
+		r0 = rf(endTime)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// GetMaxRowsCutoff provides a mock function with given fields: maxRows
+func (_m *AuditStore) GetMaxRowsCutoff(maxRows int64) store.StoreChannel {
+	ret := _m.Called(maxRows)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(int64) store.StoreChannel); ok {
+		r0 = rf(maxRows)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // PermanentDeleteBatch provides a mock function with given fields: endTime, limit
 func (_m *AuditStore) PermanentDeleteBatch(endTime int64, limit int64) store.StoreChannel {
 	ret := _m.Called(endTime, limit)
@@ -12,6 +12,38 @@ type ChannelMemberHistoryStore struct {
 	mock.Mock
 }
 
+// GetChannelHistory provides a mock function with given fields: channelId, since, until, offset, limit
+func (_m *ChannelMemberHistoryStore) GetChannelHistory(channelId string, since int64, until int64, offset int, limit int) store.StoreChannel {
+	ret := _m.Called(channelId, since, until, offset, limit)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, int64, int64, int, int) store.StoreChannel); ok {
+		r0 = rf(channelId, since, until, offset, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// GetLastLeaveTime provides a mock function with given fields: userId, channelId
+func (_m *ChannelMemberHistoryStore) GetLastLeaveTime(userId string, channelId string) store.StoreChannel {
+	ret := _m.Called(userId, channelId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, string) store.StoreChannel); ok {
+		r0 = rf(userId, channelId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // GetUsersInChannelDuring provides a mock function with given fields: startTime, endTime, channelId
 func (_m *ChannelMemberHistoryStore) GetUsersInChannelDuring(startTime int64, endTime int64, channelId string) store.StoreChannel {
 	ret := _m.Called(startTime, endTime, channelId)
@@ -28,13 +60,13 @@ func (_m *ChannelMemberHistoryStore) GetUsersInChannelDuring(startTime int64, en
 	return r0
 }
 
-// LogJoinEvent provides a mock function with given fields: userId, channelId, joinTime
-func (_m *ChannelMemberHistoryStore) LogJoinEvent(userId string, channelId string, joinTime int64) store.StoreChannel {
-	ret := _m.Called(userId, channelId, joinTime)
+// LogJoinEvent provides a mock function with given fields: userId, channelId, joinTime, actorId
+func (_m *ChannelMemberHistoryStore) LogJoinEvent(userId string, channelId string, joinTime int64, actorId string) store.StoreChannel {
+	ret := _m.Called(userId, channelId, joinTime, actorId)
 
 	var r0 store.StoreChannel
-	if rf, ok := ret.Get(0).(func(string, string, int64) store.StoreChannel); ok {
-		r0 = rf(userId, channelId, joinTime)
+	if rf, ok := ret.Get(0).(func(string, string, int64, string) store.StoreChannel); ok {
+		r0 = rf(userId, channelId, joinTime, actorId)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(store.StoreChannel)
@@ -44,13 +76,13 @@ func (_m *ChannelMemberHistoryStore) LogJoinEvent(userId string, channelId strin
 	return r0
 }
 
-// LogLeaveEvent provides a mock function with given fields: userId, channelId, leaveTime
-func (_m *ChannelMemberHistoryStore) LogLeaveEvent(userId string, channelId string, leaveTime int64) store.StoreChannel {
-	ret := _m.Called(userId, channelId, leaveTime)
+// LogLeaveEvent provides a mock function with given fields: userId, channelId, leaveTime, actorId, reason
+func (_m *ChannelMemberHistoryStore) LogLeaveEvent(userId string, channelId string, leaveTime int64, actorId string, reason string) store.StoreChannel {
+	ret := _m.Called(userId, channelId, leaveTime, actorId, reason)
 
 	var r0 store.StoreChannel
-	if rf, ok := ret.Get(0).(func(string, string, int64) store.StoreChannel); ok {
-		r0 = rf(userId, channelId, leaveTime)
+	if rf, ok := ret.Get(0).(func(string, string, int64, string, string) store.StoreChannel); ok {
+		r0 = rf(userId, channelId, leaveTime, actorId, reason)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(store.StoreChannel)
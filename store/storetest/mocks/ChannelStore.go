@@ -29,6 +29,22 @@ func (_m *ChannelStore) AnalyticsDeletedTypeCount(teamId string, channelType str
 	return r0
 }
 
+// AnalyticsFeedCount provides a mock function with given fields:
+func (_m *ChannelStore) AnalyticsFeedCount() store.StoreChannel {
+	ret := _m.Called()
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func() store.StoreChannel); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // AnalyticsTypeCount provides a mock function with given fields: teamId, channelType
 func (_m *ChannelStore) AnalyticsTypeCount(teamId string, channelType string) store.StoreChannel {
 	ret := _m.Called(teamId, channelType)
@@ -45,6 +61,22 @@ func (_m *ChannelStore) AnalyticsTypeCount(teamId string, channelType string) st
 	return r0
 }
 
+// ArchiveDirectChannelsForUser provides a mock function with given fields: userId, deleteAt
+func (_m *ChannelStore) ArchiveDirectChannelsForUser(userId string, deleteAt int64) store.StoreChannel {
+	ret := _m.Called(userId, deleteAt)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, int64) store.StoreChannel); ok {
+		r0 = rf(userId, deleteAt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // AutocompleteInTeam provides a mock function with given fields: teamId, term
 func (_m *ChannelStore) AutocompleteInTeam(teamId string, term string) store.StoreChannel {
 	ret := _m.Called(teamId, term)
@@ -82,6 +114,38 @@ func (_m *ChannelStore) CreateDirectChannel(userId string, otherUserId string) s
 	return r0
 }
 
+// CreateInitialSidebarCategories provides a mock function with given fields: userId, teamId
+func (_m *ChannelStore) CreateInitialSidebarCategories(userId string, teamId string) store.StoreChannel {
+	ret := _m.Called(userId, teamId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, string) store.StoreChannel); ok {
+		r0 = rf(userId, teamId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// CreateSidebarCategory provides a mock function with given fields: category
+func (_m *ChannelStore) CreateSidebarCategory(category *model.SidebarCategoryWithChannels) store.StoreChannel {
+	ret := _m.Called(category)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(*model.SidebarCategoryWithChannels) store.StoreChannel); ok {
+		r0 = rf(category)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // Delete provides a mock function with given fields: channelId, time
 func (_m *ChannelStore) Delete(channelId string, time int64) store.StoreChannel {
 	ret := _m.Called(channelId, time)
@@ -98,6 +162,54 @@ func (_m *ChannelStore) Delete(channelId string, time int64) store.StoreChannel
 	return r0
 }
 
+// DeleteNameHistory provides a mock function with given fields: teamId, name
+func (_m *ChannelStore) DeleteNameHistory(teamId string, name string) store.StoreChannel {
+	ret := _m.Called(teamId, name)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, string) store.StoreChannel); ok {
+		r0 = rf(teamId, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// DeleteSidebarCategoriesForTeamMember provides a mock function with given fields: userId, teamId
+func (_m *ChannelStore) DeleteSidebarCategoriesForTeamMember(userId string, teamId string) store.StoreChannel {
+	ret := _m.Called(userId, teamId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, string) store.StoreChannel); ok {
+		r0 = rf(userId, teamId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// DeleteSidebarCategory provides a mock function with given fields: categoryId
+func (_m *ChannelStore) DeleteSidebarCategory(categoryId string) store.StoreChannel {
+	ret := _m.Called(categoryId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string) store.StoreChannel); ok {
+		r0 = rf(categoryId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // Get provides a mock function with given fields: id, allowFromCache
 func (_m *ChannelStore) Get(id string, allowFromCache bool) store.StoreChannel {
 	ret := _m.Called(id, allowFromCache)
@@ -162,6 +274,38 @@ func (_m *ChannelStore) GetAllChannelMembersNotifyPropsForChannel(channelId stri
 	return r0
 }
 
+// GetBatchForTeam provides a mock function with given fields: teamId, limit
+func (_m *ChannelStore) GetBatchForTeam(teamId string, limit int) store.StoreChannel {
+	ret := _m.Called(teamId, limit)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, int) store.StoreChannel); ok {
+		r0 = rf(teamId, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// GetByIconEmojiName provides a mock function with given fields: emojiName
+func (_m *ChannelStore) GetByIconEmojiName(emojiName string) store.StoreChannel {
+	ret := _m.Called(emojiName)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string) store.StoreChannel); ok {
+		r0 = rf(emojiName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // GetByName provides a mock function with given fields: team_id, name, allowFromCache
 func (_m *ChannelStore) GetByName(team_id string, name string, allowFromCache bool) store.StoreChannel {
 	ret := _m.Called(team_id, name, allowFromCache)
@@ -242,6 +386,22 @@ func (_m *ChannelStore) GetChannelUnread(channelId string, userId string) store.
 	return r0
 }
 
+// GetChannelUnreadsForUser provides a mock function with given fields: teamId, userId
+func (_m *ChannelStore) GetChannelUnreadsForUser(teamId string, userId string) store.StoreChannel {
+	ret := _m.Called(teamId, userId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, string) store.StoreChannel); ok {
+		r0 = rf(teamId, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // GetChannels provides a mock function with given fields: teamId, userId
 func (_m *ChannelStore) GetChannels(teamId string, userId string) store.StoreChannel {
 	ret := _m.Called(teamId, userId)
@@ -290,6 +450,22 @@ func (_m *ChannelStore) GetDeletedByName(team_id string, name string) store.Stor
 	return r0
 }
 
+// GetDueForPermanentDeletion provides a mock function with given fields: before, limit
+func (_m *ChannelStore) GetDueForPermanentDeletion(before int64, limit int) store.StoreChannel {
+	ret := _m.Called(before, limit)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(int64, int) store.StoreChannel); ok {
+		r0 = rf(before, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // GetForPost provides a mock function with given fields: postId
 func (_m *ChannelStore) GetForPost(postId string) store.StoreChannel {
 	ret := _m.Called(postId)
@@ -322,6 +498,38 @@ func (_m *ChannelStore) GetFromMaster(id string) store.StoreChannel {
 	return r0
 }
 
+// GetGuestCount provides a mock function with given fields: channelId
+func (_m *ChannelStore) GetGuestCount(channelId string) store.StoreChannel {
+	ret := _m.Called(channelId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string) store.StoreChannel); ok {
+		r0 = rf(channelId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// GetInactivePublicChannels provides a mock function with given fields: inactiveSince, afterChannelId, limit
+func (_m *ChannelStore) GetInactivePublicChannels(inactiveSince int64, afterChannelId string, limit int) store.StoreChannel {
+	ret := _m.Called(inactiveSince, afterChannelId, limit)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(int64, string, int) store.StoreChannel); ok {
+		r0 = rf(inactiveSince, afterChannelId, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // GetMember provides a mock function with given fields: channelId, userId
 func (_m *ChannelStore) GetMember(channelId string, userId string) store.StoreChannel {
 	ret := _m.Called(channelId, userId)
@@ -432,6 +640,38 @@ func (_m *ChannelStore) GetMembersForUser(teamId string, userId string) store.St
 	return r0
 }
 
+// GetMembersWithUnreadMentions provides a mock function with given fields: channelId, viewedBefore
+func (_m *ChannelStore) GetMembersWithUnreadMentions(channelId string, viewedBefore int64) store.StoreChannel {
+	ret := _m.Called(channelId, viewedBefore)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, int64) store.StoreChannel); ok {
+		r0 = rf(channelId, viewedBefore)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// GetModeratedPermissions provides a mock function with given fields: channelId
+func (_m *ChannelStore) GetModeratedPermissions(channelId string) store.StoreChannel {
+	ret := _m.Called(channelId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string) store.StoreChannel); ok {
+		r0 = rf(channelId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // GetMoreChannels provides a mock function with given fields: teamId, userId, offset, limit
 func (_m *ChannelStore) GetMoreChannels(teamId string, userId string, offset int, limit int) store.StoreChannel {
 	ret := _m.Called(teamId, userId, offset, limit)
@@ -448,6 +688,38 @@ func (_m *ChannelStore) GetMoreChannels(teamId string, userId string, offset int
 	return r0
 }
 
+// GetNameHistory provides a mock function with given fields: channelId
+func (_m *ChannelStore) GetNameHistory(channelId string) store.StoreChannel {
+	ret := _m.Called(channelId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string) store.StoreChannel); ok {
+		r0 = rf(channelId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// GetNameHistoryByName provides a mock function with given fields: teamId, name
+func (_m *ChannelStore) GetNameHistoryByName(teamId string, name string) store.StoreChannel {
+	ret := _m.Called(teamId, name)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, string) store.StoreChannel); ok {
+		r0 = rf(teamId, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // GetPinnedPosts provides a mock function with given fields: channelId
 func (_m *ChannelStore) GetPinnedPosts(channelId string) store.StoreChannel {
 	ret := _m.Called(channelId)
@@ -496,6 +768,38 @@ func (_m *ChannelStore) GetPublicChannelsForTeam(teamId string, offset int, limi
 	return r0
 }
 
+// GetSidebarCategories provides a mock function with given fields: userId, teamId
+func (_m *ChannelStore) GetSidebarCategories(userId string, teamId string) store.StoreChannel {
+	ret := _m.Called(userId, teamId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, string) store.StoreChannel); ok {
+		r0 = rf(userId, teamId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// GetSidebarCategory provides a mock function with given fields: categoryId
+func (_m *ChannelStore) GetSidebarCategory(categoryId string) store.StoreChannel {
+	ret := _m.Called(categoryId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string) store.StoreChannel); ok {
+		r0 = rf(categoryId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // GetTeamChannels provides a mock function with given fields: teamId
 func (_m *ChannelStore) GetTeamChannels(teamId string) store.StoreChannel {
 	ret := _m.Called(teamId)
@@ -512,6 +816,22 @@ func (_m *ChannelStore) GetTeamChannels(teamId string) store.StoreChannel {
 	return r0
 }
 
+// GetViewedAt provides a mock function with given fields: channelIds
+func (_m *ChannelStore) GetViewedAt(channelIds []string) store.StoreChannel {
+	ret := _m.Called(channelIds)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func([]string) store.StoreChannel); ok {
+		r0 = rf(channelIds)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // IncrementMentionCount provides a mock function with given fields: channelId, userId
 func (_m *ChannelStore) IncrementMentionCount(channelId string, userId string) store.StoreChannel {
 	ret := _m.Called(channelId, userId)
@@ -528,6 +848,22 @@ func (_m *ChannelStore) IncrementMentionCount(channelId string, userId string) s
 	return r0
 }
 
+// IncrementMsgCountForUsersWithPreference provides a mock function with given fields: channelId, category, name, value
+func (_m *ChannelStore) IncrementMsgCountForUsersWithPreference(channelId string, category string, name string, value string) store.StoreChannel {
+	ret := _m.Called(channelId, category, name, value)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, string, string, string) store.StoreChannel); ok {
+		r0 = rf(channelId, category, name, value)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // InvalidateAllChannelMembersForUser provides a mock function with given fields: userId
 func (_m *ChannelStore) InvalidateAllChannelMembersForUser(userId string) {
 	_m.Called(userId)
@@ -631,6 +967,38 @@ func (_m *ChannelStore) PermanentDeleteMembersByUser(userId string) store.StoreC
 	return r0
 }
 
+// ReconcileMemberCounts provides a mock function with given fields: afterChannelId, limit
+func (_m *ChannelStore) ReconcileMemberCounts(afterChannelId string, limit int) store.StoreChannel {
+	ret := _m.Called(afterChannelId, limit)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, int) store.StoreChannel); ok {
+		r0 = rf(afterChannelId, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// RemoveChannelFromSidebarCategories provides a mock function with given fields: userId, channelId
+func (_m *ChannelStore) RemoveChannelFromSidebarCategories(userId string, channelId string) store.StoreChannel {
+	ret := _m.Called(userId, channelId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, string) store.StoreChannel); ok {
+		r0 = rf(userId, channelId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // RemoveMember provides a mock function with given fields: channelId, userId
 func (_m *ChannelStore) RemoveMember(channelId string, userId string) store.StoreChannel {
 	ret := _m.Called(channelId, userId)
@@ -647,6 +1015,22 @@ func (_m *ChannelStore) RemoveMember(channelId string, userId string) store.Stor
 	return r0
 }
 
+// RemovePostsFromCounts provides a mock function with given fields: channelId, posts, mentionCounts
+func (_m *ChannelStore) RemovePostsFromCounts(channelId string, posts []*model.Post, mentionCounts map[string]int64) store.StoreChannel {
+	ret := _m.Called(channelId, posts, mentionCounts)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, []*model.Post, map[string]int64) store.StoreChannel); ok {
+		r0 = rf(channelId, posts, mentionCounts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // Restore provides a mock function with given fields: channelId, time
 func (_m *ChannelStore) Restore(channelId string, time int64) store.StoreChannel {
 	ret := _m.Called(channelId, time)
@@ -663,6 +1047,22 @@ func (_m *ChannelStore) Restore(channelId string, time int64) store.StoreChannel
 	return r0
 }
 
+// RestoreDirectChannelsForUser provides a mock function with given fields: userId, updateAt
+func (_m *ChannelStore) RestoreDirectChannelsForUser(userId string, updateAt int64) store.StoreChannel {
+	ret := _m.Called(userId, updateAt)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, int64) store.StoreChannel); ok {
+		r0 = rf(userId, updateAt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // Save provides a mock function with given fields: channel, maxChannelsPerTeam
 func (_m *ChannelStore) Save(channel *model.Channel, maxChannelsPerTeam int64) store.StoreChannel {
 	ret := _m.Called(channel, maxChannelsPerTeam)
@@ -711,6 +1111,38 @@ func (_m *ChannelStore) SaveMember(member *model.ChannelMember) store.StoreChann
 	return r0
 }
 
+// SaveNameHistory provides a mock function with given fields: history, maxPerChannel
+func (_m *ChannelStore) SaveNameHistory(history *model.ChannelNameHistory, maxPerChannel int) store.StoreChannel {
+	ret := _m.Called(history, maxPerChannel)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(*model.ChannelNameHistory, int) store.StoreChannel); ok {
+		r0 = rf(history, maxPerChannel)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// SearchForUserInTeam provides a mock function with given fields: userId, teamId, term
+func (_m *ChannelStore) SearchForUserInTeam(userId string, teamId string, term string) store.StoreChannel {
+	ret := _m.Called(userId, teamId, term)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, string, string) store.StoreChannel); ok {
+		r0 = rf(userId, teamId, term)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // SearchInTeam provides a mock function with given fields: teamId, term
 func (_m *ChannelStore) SearchInTeam(teamId string, term string) store.StoreChannel {
 	ret := _m.Called(teamId, term)
@@ -759,6 +1191,22 @@ func (_m *ChannelStore) SetDeleteAt(channelId string, deleteAt int64, updateAt i
 	return r0
 }
 
+// SetPermanentDeleteAt provides a mock function with given fields: channelId, permanentDeleteAt, updateAt
+func (_m *ChannelStore) SetPermanentDeleteAt(channelId string, permanentDeleteAt int64, updateAt int64) store.StoreChannel {
+	ret := _m.Called(channelId, permanentDeleteAt, updateAt)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, int64, int64) store.StoreChannel); ok {
+		r0 = rf(channelId, permanentDeleteAt, updateAt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // Update provides a mock function with given fields: channel
 func (_m *ChannelStore) Update(channel *model.Channel) store.StoreChannel {
 	ret := _m.Called(channel)
@@ -775,6 +1223,38 @@ func (_m *ChannelStore) Update(channel *model.Channel) store.StoreChannel {
 	return r0
 }
 
+// UpdateLastReadPost provides a mock function with given fields: post, userId
+func (_m *ChannelStore) UpdateLastReadPost(post *model.Post, userId string) store.StoreChannel {
+	ret := _m.Called(post, userId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(*model.Post, string) store.StoreChannel); ok {
+		r0 = rf(post, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// UpdateLastReadPostsToLatest provides a mock function with given fields: channelIds, userId
+func (_m *ChannelStore) UpdateLastReadPostsToLatest(channelIds []string, userId string) store.StoreChannel {
+	ret := _m.Called(channelIds, userId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func([]string, string) store.StoreChannel); ok {
+		r0 = rf(channelIds, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // UpdateLastViewedAt provides a mock function with given fields: channelIds, userId
 func (_m *ChannelStore) UpdateLastViewedAt(channelIds []string, userId string) store.StoreChannel {
 	ret := _m.Called(channelIds, userId)
@@ -791,6 +1271,22 @@ func (_m *ChannelStore) UpdateLastViewedAt(channelIds []string, userId string) s
 	return r0
 }
 
+// UpdateLastViewedAtPost provides a mock function with given fields: unreadPost, userId, mentionCount
+func (_m *ChannelStore) UpdateLastViewedAtPost(unreadPost *model.Post, userId string, mentionCount int64) store.StoreChannel {
+	ret := _m.Called(unreadPost, userId, mentionCount)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(*model.Post, string, int64) store.StoreChannel); ok {
+		r0 = rf(unreadPost, userId, mentionCount)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // UpdateMember provides a mock function with given fields: member
 func (_m *ChannelStore) UpdateMember(member *model.ChannelMember) store.StoreChannel {
 	ret := _m.Called(member)
@@ -806,3 +1302,99 @@ func (_m *ChannelStore) UpdateMember(member *model.ChannelMember) store.StoreCha
 
 	return r0
 }
+
+// UpdateMembers provides a mock function with given fields: members
+func (_m *ChannelStore) UpdateMembers(members []*model.ChannelMember) store.StoreChannel {
+	ret := _m.Called(members)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func([]*model.ChannelMember) store.StoreChannel); ok {
+		r0 = rf(members)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// UpdateModeratedPermissions provides a mock function with given fields: channelId, moderated
+func (_m *ChannelStore) UpdateModeratedPermissions(channelId string, moderated *model.ChannelModeratedPermissions) store.StoreChannel {
+	ret := _m.Called(channelId, moderated)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, *model.ChannelModeratedPermissions) store.StoreChannel); ok {
+		r0 = rf(channelId, moderated)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// UpdateSidebarCategories provides a mock function with given fields: userId, teamId, categories
+func (_m *ChannelStore) UpdateSidebarCategories(userId string, teamId string, categories []*model.SidebarCategoryWithChannels) store.StoreChannel {
+	ret := _m.Called(userId, teamId, categories)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, string, []*model.SidebarCategoryWithChannels) store.StoreChannel); ok {
+		r0 = rf(userId, teamId, categories)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// UpdateSidebarCategoryOrder provides a mock function with given fields: userId, teamId, categoryOrder
+func (_m *ChannelStore) UpdateSidebarCategoryOrder(userId string, teamId string, categoryOrder []string) store.StoreChannel {
+	ret := _m.Called(userId, teamId, categoryOrder)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, string, []string) store.StoreChannel); ok {
+		r0 = rf(userId, teamId, categoryOrder)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// UpdateViewedAt provides a mock function with given fields: userId, views
+func (_m *ChannelStore) UpdateViewedAt(userId string, views []*model.ChannelViewedAt) store.StoreChannel {
+	ret := _m.Called(userId, views)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, []*model.ChannelViewedAt) store.StoreChannel); ok {
+		r0 = rf(userId, views)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// UpdateWithWebSocketEvent provides a mock function with given fields: channel, event
+func (_m *ChannelStore) UpdateWithWebSocketEvent(channel *model.Channel, event *model.WebSocketOutboxEvent) store.StoreChannel {
+	ret := _m.Called(channel, event)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(*model.Channel, *model.WebSocketOutboxEvent) store.StoreChannel); ok {
+		r0 = rf(channel, event)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
@@ -29,6 +29,22 @@ func (_m *ComplianceStore) ComplianceExport(compliance *model.Compliance) store.
 	return r0
 }
 
+// ExportPosts provides a mock function with given fields: startTime, endTime, afterCreateAt, afterId, limit
+func (_m *ComplianceStore) ExportPosts(startTime int64, endTime int64, afterCreateAt int64, afterId string, limit int) store.StoreChannel {
+	ret := _m.Called(startTime, endTime, afterCreateAt, afterId, limit)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(int64, int64, int64, string, int) store.StoreChannel); ok {
+		r0 = rf(startTime, endTime, afterCreateAt, afterId, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // Get provides a mock function with given fields: id
 func (_m *ComplianceStore) Get(id string) store.StoreChannel {
 	ret := _m.Called(id)
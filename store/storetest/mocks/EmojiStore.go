@@ -77,6 +77,70 @@ func (_m *EmojiStore) GetList(offset int, limit int, sort string) store.StoreCha
 	return r0
 }
 
+// GetMultipleByName provides a mock function with given fields: names
+func (_m *EmojiStore) GetMultipleByName(names []string) store.StoreChannel {
+	ret := _m.Called(names)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func([]string) store.StoreChannel); ok {
+		r0 = rf(names)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// GetStats provides a mock function with given fields: offset, limit
+func (_m *EmojiStore) GetStats(offset int, limit int) store.StoreChannel {
+	ret := _m.Called(offset, limit)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(int, int) store.StoreChannel); ok {
+		r0 = rf(offset, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// GetUnused provides a mock function with given fields: before
+func (_m *EmojiStore) GetUnused(before int64) store.StoreChannel {
+	ret := _m.Called(before)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(int64) store.StoreChannel); ok {
+		r0 = rf(before)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// RollUpStats provides a mock function with given fields: updateAt
+func (_m *EmojiStore) RollUpStats(updateAt int64) store.StoreChannel {
+	ret := _m.Called(updateAt)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(int64) store.StoreChannel); ok {
+		r0 = rf(updateAt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // Save provides a mock function with given fields: emoji
 func (_m *EmojiStore) Save(emoji *model.Emoji) store.StoreChannel {
 	ret := _m.Called(emoji)
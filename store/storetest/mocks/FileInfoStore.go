@@ -13,6 +13,22 @@ type FileInfoStore struct {
 	mock.Mock
 }
 
+// AnalyticsCount provides a mock function with given fields:
+func (_m *FileInfoStore) AnalyticsCount() store.StoreChannel {
+	ret := _m.Called()
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func() store.StoreChannel); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // AttachToPost provides a mock function with given fields: fileId, postId
 func (_m *FileInfoStore) AttachToPost(fileId string, postId string) store.StoreChannel {
 	ret := _m.Called(fileId, postId)
@@ -66,6 +82,38 @@ func (_m *FileInfoStore) Get(id string) store.StoreChannel {
 	return r0
 }
 
+// GetBatchAfterId provides a mock function with given fields: afterId, limit
+func (_m *FileInfoStore) GetBatchAfterId(afterId string, limit int) store.StoreChannel {
+	ret := _m.Called(afterId, limit)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, int) store.StoreChannel); ok {
+		r0 = rf(afterId, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// GetByHash provides a mock function with given fields: hash, size, creatorId
+func (_m *FileInfoStore) GetByHash(hash string, size int64, creatorId string) store.StoreChannel {
+	ret := _m.Called(hash, size, creatorId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, int64, string) store.StoreChannel); ok {
+		r0 = rf(hash, size, creatorId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // GetByPath provides a mock function with given fields: path
 func (_m *FileInfoStore) GetByPath(path string) store.StoreChannel {
 	ret := _m.Called(path)
@@ -98,6 +146,86 @@ func (_m *FileInfoStore) GetForPost(postId string, readFromMaster bool, allowFro
 	return r0
 }
 
+// GetForPosts provides a mock function with given fields: postIds
+func (_m *FileInfoStore) GetForPosts(postIds []string) store.StoreChannel {
+	ret := _m.Called(postIds)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func([]string) store.StoreChannel); ok {
+		r0 = rf(postIds)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// GetForUser provides a mock function with given fields: userId
+func (_m *FileInfoStore) GetForUser(userId string) store.StoreChannel {
+	ret := _m.Called(userId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string) store.StoreChannel); ok {
+		r0 = rf(userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// GetReferencedPaths provides a mock function with given fields: paths
+func (_m *FileInfoStore) GetReferencedPaths(paths []string) store.StoreChannel {
+	ret := _m.Called(paths)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func([]string) store.StoreChannel); ok {
+		r0 = rf(paths)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// GetStatsWithFilter provides a mock function with given fields: filter
+func (_m *FileInfoStore) GetStatsWithFilter(filter *model.FileInfoFilter) store.StoreChannel {
+	ret := _m.Called(filter)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(*model.FileInfoFilter) store.StoreChannel); ok {
+		r0 = rf(filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// GetWithFilter provides a mock function with given fields: filter, offset, limit
+func (_m *FileInfoStore) GetWithFilter(filter *model.FileInfoFilter, offset int, limit int) store.StoreChannel {
+	ret := _m.Called(filter, offset, limit)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(*model.FileInfoFilter, int, int) store.StoreChannel); ok {
+		r0 = rf(filter, offset, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // InvalidateFileInfosForPostCache provides a mock function with given fields: postId
 func (_m *FileInfoStore) InvalidateFileInfosForPostCache(postId string) {
 	_m.Called(postId)
@@ -135,6 +263,22 @@ func (_m *FileInfoStore) PermanentDeleteBatch(endTime int64, limit int64) store.
 	return r0
 }
 
+// PermanentDeleteForChannelBatch provides a mock function with given fields: channelId, limit
+func (_m *FileInfoStore) PermanentDeleteForChannelBatch(channelId string, limit int64) store.StoreChannel {
+	ret := _m.Called(channelId, limit)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, int64) store.StoreChannel); ok {
+		r0 = rf(channelId, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // Save provides a mock function with given fields: info
 func (_m *FileInfoStore) Save(info *model.FileInfo) store.StoreChannel {
 	ret := _m.Called(info)
@@ -150,3 +294,35 @@ func (_m *FileInfoStore) Save(info *model.FileInfo) store.StoreChannel {
 
 	return r0
 }
+
+// SetContentType provides a mock function with given fields: fileId, contentType
+func (_m *FileInfoStore) SetContentType(fileId string, contentType string) store.StoreChannel {
+	ret := _m.Called(fileId, contentType)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, string) store.StoreChannel); ok {
+		r0 = rf(fileId, contentType)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// SetMissingAt provides a mock function with given fields: fileIds, missingAt
+func (_m *FileInfoStore) SetMissingAt(fileIds []string, missingAt int64) store.StoreChannel {
+	ret := _m.Called(fileIds, missingAt)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func([]string, int64) store.StoreChannel); ok {
+		r0 = rf(fileIds, missingAt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
@@ -125,6 +125,38 @@ func (_m *JobStore) GetCountByStatusAndType(status string, jobType string) store
 	return r0
 }
 
+// GetFinishedCountBefore provides a mock function with given fields: endTime
+func (_m *JobStore) GetFinishedCountBefore(endTime int64) store.StoreChannel {
+	ret := _m.Called(endTime)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(int64) store.StoreChannel); ok {
+		r0 = rf(endTime)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// GetFinishedMaxRowsCutoff provides a mock function with given fields: maxRows
+func (_m *JobStore) GetFinishedMaxRowsCutoff(maxRows int64) store.StoreChannel {
+	ret := _m.Called(maxRows)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(int64) store.StoreChannel); ok {
+		r0 = rf(maxRows)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // GetNewestJobByStatusAndType provides a mock function with given fields: status, jobType
 func (_m *JobStore) GetNewestJobByStatusAndType(status string, jobType string) store.StoreChannel {
 	ret := _m.Called(status, jobType)
@@ -141,6 +173,22 @@ func (_m *JobStore) GetNewestJobByStatusAndType(status string, jobType string) s
 	return r0
 }
 
+// PermanentDeleteFinishedBatch provides a mock function with given fields: endTime, limit
+func (_m *JobStore) PermanentDeleteFinishedBatch(endTime int64, limit int64) store.StoreChannel {
+	ret := _m.Called(endTime, limit)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(int64, int64) store.StoreChannel); ok {
+		r0 = rf(endTime, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // Save provides a mock function with given fields: job
 func (_m *JobStore) Save(job *model.Job) store.StoreChannel {
 	ret := _m.Called(job)
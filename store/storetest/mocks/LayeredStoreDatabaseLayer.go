@@ -14,6 +14,22 @@ type LayeredStoreDatabaseLayer struct {
 	mock.Mock
 }
 
+// AnalyticsDaily provides a mock function with given fields:
+func (_m *LayeredStoreDatabaseLayer) AnalyticsDaily() store.AnalyticsDailyStore {
+	ret := _m.Called()
+
+	var r0 store.AnalyticsDailyStore
+	if rf, ok := ret.Get(0).(func() store.AnalyticsDailyStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.AnalyticsDailyStore)
+		}
+	}
+
+	return r0
+}
+
 // Audit provides a mock function with given fields:
 func (_m *LayeredStoreDatabaseLayer) Audit() store.AuditStore {
 	ret := _m.Called()
@@ -30,6 +46,22 @@ func (_m *LayeredStoreDatabaseLayer) Audit() store.AuditStore {
 	return r0
 }
 
+// BlockedDomain provides a mock function with given fields:
+func (_m *LayeredStoreDatabaseLayer) BlockedDomain() store.BlockedDomainStore {
+	ret := _m.Called()
+
+	var r0 store.BlockedDomainStore
+	if rf, ok := ret.Get(0).(func() store.BlockedDomainStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.BlockedDomainStore)
+		}
+	}
+
+	return r0
+}
+
 // Channel provides a mock function with given fields:
 func (_m *LayeredStoreDatabaseLayer) Channel() store.ChannelStore {
 	ret := _m.Called()
@@ -131,6 +163,38 @@ func (_m *LayeredStoreDatabaseLayer) Compliance() store.ComplianceStore {
 	return r0
 }
 
+// ConfigurationHistory provides a mock function with given fields:
+func (_m *LayeredStoreDatabaseLayer) ConfigurationHistory() store.ConfigurationHistoryStore {
+	ret := _m.Called()
+
+	var r0 store.ConfigurationHistoryStore
+	if rf, ok := ret.Get(0).(func() store.ConfigurationHistoryStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.ConfigurationHistoryStore)
+		}
+	}
+
+	return r0
+}
+
+// CustomGroup provides a mock function with given fields:
+func (_m *LayeredStoreDatabaseLayer) CustomGroup() store.CustomGroupStore {
+	ret := _m.Called()
+
+	var r0 store.CustomGroupStore
+	if rf, ok := ret.Get(0).(func() store.CustomGroupStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.CustomGroupStore)
+		}
+	}
+
+	return r0
+}
+
 // DropAllTables provides a mock function with given fields:
 func (_m *LayeredStoreDatabaseLayer) DropAllTables() {
 	_m.Called()
@@ -168,6 +232,96 @@ func (_m *LayeredStoreDatabaseLayer) FileInfo() store.FileInfoStore {
 	return r0
 }
 
+// FilePublicLink provides a mock function with given fields:
+func (_m *LayeredStoreDatabaseLayer) FilePublicLink() store.FilePublicLinkStore {
+	ret := _m.Called()
+
+	var r0 store.FilePublicLinkStore
+	if rf, ok := ret.Get(0).(func() store.FilePublicLinkStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.FilePublicLinkStore)
+		}
+	}
+
+	return r0
+}
+
+// FileStorageUsage provides a mock function with given fields:
+func (_m *LayeredStoreDatabaseLayer) FileStorageUsage() store.FileStorageUsageStore {
+	ret := _m.Called()
+
+	var r0 store.FileStorageUsageStore
+	if rf, ok := ret.Get(0).(func() store.FileStorageUsageStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.FileStorageUsageStore)
+		}
+	}
+
+	return r0
+}
+
+// InviteLink provides a mock function with given fields:
+func (_m *LayeredStoreDatabaseLayer) InviteLink() store.InviteLinkStore {
+	ret := _m.Called()
+
+	var r0 store.InviteLinkStore
+	if rf, ok := ret.Get(0).(func() store.InviteLinkStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.InviteLinkStore)
+		}
+	}
+
+	return r0
+}
+
+// IsFullUnicodeSupported provides a mock function with given fields:
+func (_m *LayeredStoreDatabaseLayer) IsFullUnicodeSupported() bool {
+	ret := _m.Called()
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// IsMasterHealthy provides a mock function with given fields:
+func (_m *LayeredStoreDatabaseLayer) IsMasterHealthy() bool {
+	ret := _m.Called()
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// IsReadOnly provides a mock function with given fields:
+func (_m *LayeredStoreDatabaseLayer) IsReadOnly() bool {
+	ret := _m.Called()
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // Job provides a mock function with given fields:
 func (_m *LayeredStoreDatabaseLayer) Job() store.JobStore {
 	ret := _m.Called()
@@ -269,6 +423,22 @@ func (_m *LayeredStoreDatabaseLayer) Post() store.PostStore {
 	return r0
 }
 
+// PostIdempotencyKey provides a mock function with given fields:
+func (_m *LayeredStoreDatabaseLayer) PostIdempotencyKey() store.PostIdempotencyKeyStore {
+	ret := _m.Called()
+
+	var r0 store.PostIdempotencyKeyStore
+	if rf, ok := ret.Get(0).(func() store.PostIdempotencyKeyStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.PostIdempotencyKeyStore)
+		}
+	}
+
+	return r0
+}
+
 // Preference provides a mock function with given fields:
 func (_m *LayeredStoreDatabaseLayer) Preference() store.PreferenceStore {
 	ret := _m.Called()
@@ -285,6 +455,22 @@ func (_m *LayeredStoreDatabaseLayer) Preference() store.PreferenceStore {
 	return r0
 }
 
+// ProvisioningToken provides a mock function with given fields:
+func (_m *LayeredStoreDatabaseLayer) ProvisioningToken() store.ProvisioningTokenStore {
+	ret := _m.Called()
+
+	var r0 store.ProvisioningTokenStore
+	if rf, ok := ret.Get(0).(func() store.ProvisioningTokenStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.ProvisioningTokenStore)
+		}
+	}
+
+	return r0
+}
+
 // Reaction provides a mock function with given fields:
 func (_m *LayeredStoreDatabaseLayer) Reaction() store.ReactionStore {
 	ret := _m.Called()
@@ -347,6 +533,29 @@ func (_m *LayeredStoreDatabaseLayer) ReactionDeleteAllWithEmojiName(ctx context.
 	return r0
 }
 
+// ReactionGetCountsForPosts provides a mock function with given fields: ctx, postIds, userId, hints
+func (_m *LayeredStoreDatabaseLayer) ReactionGetCountsForPosts(ctx context.Context, postIds []string, userId string, hints ...store.LayeredStoreHint) *store.LayeredStoreSupplierResult {
+	_va := make([]interface{}, len(hints))
+	for _i := range hints {
+		_va[_i] = hints[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, postIds, userId)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *store.LayeredStoreSupplierResult
+	if rf, ok := ret.Get(0).(func(context.Context, []string, string, ...store.LayeredStoreHint) *store.LayeredStoreSupplierResult); ok {
+		r0 = rf(ctx, postIds, userId, hints...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*store.LayeredStoreSupplierResult)
+		}
+	}
+
+	return r0
+}
+
 // ReactionGetForPost provides a mock function with given fields: ctx, postId, hints
 func (_m *LayeredStoreDatabaseLayer) ReactionGetForPost(ctx context.Context, postId string, hints ...store.LayeredStoreHint) *store.LayeredStoreSupplierResult {
 	_va := make([]interface{}, len(hints))
@@ -370,6 +579,29 @@ func (_m *LayeredStoreDatabaseLayer) ReactionGetForPost(ctx context.Context, pos
 	return r0
 }
 
+// ReactionGetForUser provides a mock function with given fields: ctx, userId, hints
+func (_m *LayeredStoreDatabaseLayer) ReactionGetForUser(ctx context.Context, userId string, hints ...store.LayeredStoreHint) *store.LayeredStoreSupplierResult {
+	_va := make([]interface{}, len(hints))
+	for _i := range hints {
+		_va[_i] = hints[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, userId)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 *store.LayeredStoreSupplierResult
+	if rf, ok := ret.Get(0).(func(context.Context, string, ...store.LayeredStoreHint) *store.LayeredStoreSupplierResult); ok {
+		r0 = rf(ctx, userId, hints...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*store.LayeredStoreSupplierResult)
+		}
+	}
+
+	return r0
+}
+
 // ReactionPermanentDeleteBatch provides a mock function with given fields: ctx, endTime, limit, hints
 func (_m *LayeredStoreDatabaseLayer) ReactionPermanentDeleteBatch(ctx context.Context, endTime int64, limit int64, hints ...store.LayeredStoreHint) *store.LayeredStoreSupplierResult {
 	_va := make([]interface{}, len(hints))
@@ -416,6 +648,22 @@ func (_m *LayeredStoreDatabaseLayer) ReactionSave(ctx context.Context, reaction
 	return r0
 }
 
+// RetentionPolicy provides a mock function with given fields:
+func (_m *LayeredStoreDatabaseLayer) RetentionPolicy() store.RetentionPolicyStore {
+	ret := _m.Called()
+
+	var r0 store.RetentionPolicyStore
+	if rf, ok := ret.Get(0).(func() store.RetentionPolicyStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.RetentionPolicyStore)
+		}
+	}
+
+	return r0
+}
+
 // Role provides a mock function with given fields:
 func (_m *LayeredStoreDatabaseLayer) Role() store.RoleStore {
 	ret := _m.Called()
@@ -568,6 +816,27 @@ func (_m *LayeredStoreDatabaseLayer) SetChainNext(_a0 store.LayeredStoreSupplier
 	_m.Called(_a0)
 }
 
+// SetReadOnly provides a mock function with given fields: readOnly
+func (_m *LayeredStoreDatabaseLayer) SetReadOnly(readOnly bool) {
+	_m.Called(readOnly)
+}
+
+// ShortPermalink provides a mock function with given fields:
+func (_m *LayeredStoreDatabaseLayer) ShortPermalink() store.ShortPermalinkStore {
+	ret := _m.Called()
+
+	var r0 store.ShortPermalinkStore
+	if rf, ok := ret.Get(0).(func() store.ShortPermalinkStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.ShortPermalinkStore)
+		}
+	}
+
+	return r0
+}
+
 // Status provides a mock function with given fields:
 func (_m *LayeredStoreDatabaseLayer) Status() store.StatusStore {
 	ret := _m.Called()
@@ -616,6 +885,70 @@ func (_m *LayeredStoreDatabaseLayer) Team() store.TeamStore {
 	return r0
 }
 
+// TeamInvitation provides a mock function with given fields:
+func (_m *LayeredStoreDatabaseLayer) TeamInvitation() store.TeamInvitationStore {
+	ret := _m.Called()
+
+	var r0 store.TeamInvitationStore
+	if rf, ok := ret.Get(0).(func() store.TeamInvitationStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.TeamInvitationStore)
+		}
+	}
+
+	return r0
+}
+
+// TeamTemplate provides a mock function with given fields:
+func (_m *LayeredStoreDatabaseLayer) TeamTemplate() store.TeamTemplateStore {
+	ret := _m.Called()
+
+	var r0 store.TeamTemplateStore
+	if rf, ok := ret.Get(0).(func() store.TeamTemplateStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.TeamTemplateStore)
+		}
+	}
+
+	return r0
+}
+
+// TermsOfService provides a mock function with given fields:
+func (_m *LayeredStoreDatabaseLayer) TermsOfService() store.TermsOfServiceStore {
+	ret := _m.Called()
+
+	var r0 store.TermsOfServiceStore
+	if rf, ok := ret.Get(0).(func() store.TermsOfServiceStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.TermsOfServiceStore)
+		}
+	}
+
+	return r0
+}
+
+// Thread provides a mock function with given fields:
+func (_m *LayeredStoreDatabaseLayer) Thread() store.ThreadStore {
+	ret := _m.Called()
+
+	var r0 store.ThreadStore
+	if rf, ok := ret.Get(0).(func() store.ThreadStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.ThreadStore)
+		}
+	}
+
+	return r0
+}
+
 // Token provides a mock function with given fields:
 func (_m *LayeredStoreDatabaseLayer) Token() store.TokenStore {
 	ret := _m.Called()
@@ -706,6 +1039,38 @@ func (_m *LayeredStoreDatabaseLayer) UserAccessToken() store.UserAccessTokenStor
 	return r0
 }
 
+// UserAttribute provides a mock function with given fields:
+func (_m *LayeredStoreDatabaseLayer) UserAttribute() store.UserAttributeStore {
+	ret := _m.Called()
+
+	var r0 store.UserAttributeStore
+	if rf, ok := ret.Get(0).(func() store.UserAttributeStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.UserAttributeStore)
+		}
+	}
+
+	return r0
+}
+
+// WebSocketOutbox provides a mock function with given fields:
+func (_m *LayeredStoreDatabaseLayer) WebSocketOutbox() store.WebSocketOutboxStore {
+	ret := _m.Called()
+
+	var r0 store.WebSocketOutboxStore
+	if rf, ok := ret.Get(0).(func() store.WebSocketOutboxStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.WebSocketOutboxStore)
+		}
+	}
+
+	return r0
+}
+
 // Webhook provides a mock function with given fields:
 func (_m *LayeredStoreDatabaseLayer) Webhook() store.WebhookStore {
 	ret := _m.Called()
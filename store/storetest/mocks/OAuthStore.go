@@ -45,6 +45,22 @@ func (_m *OAuthStore) GetAccessData(token string) store.StoreChannel {
 	return r0
 }
 
+// GetAccessDataByPreviousRefreshToken provides a mock function with given fields: token
+func (_m *OAuthStore) GetAccessDataByPreviousRefreshToken(token string) store.StoreChannel {
+	ret := _m.Called(token)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string) store.StoreChannel); ok {
+		r0 = rf(token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // GetAccessDataByRefreshToken provides a mock function with given fields: token
 func (_m *OAuthStore) GetAccessDataByRefreshToken(token string) store.StoreChannel {
 	ret := _m.Called(token)
@@ -162,6 +162,22 @@ func (_m *PostStore) GetFlaggedPostsForTeam(userId string, teamId string, offset
 	return r0
 }
 
+// GetHistory provides a mock function with given fields: postId
+func (_m *PostStore) GetHistory(postId string) store.StoreChannel {
+	ret := _m.Called(postId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string) store.StoreChannel); ok {
+		r0 = rf(postId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // GetMaxPostSize provides a mock function with given fields:
 func (_m *PostStore) GetMaxPostSize() store.StoreChannel {
 	ret := _m.Called()
@@ -274,6 +290,22 @@ func (_m *PostStore) GetPostsByIds(postIds []string) store.StoreChannel {
 	return r0
 }
 
+// GetPostsByUser provides a mock function with given fields: userId, offset, limit
+func (_m *PostStore) GetPostsByUser(userId string, offset int, limit int) store.StoreChannel {
+	ret := _m.Called(userId, offset, limit)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, int, int) store.StoreChannel); ok {
+		r0 = rf(userId, offset, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // GetPostsCreatedAt provides a mock function with given fields: channelId, time
 func (_m *PostStore) GetPostsCreatedAt(channelId string, time int64) store.StoreChannel {
 	ret := _m.Called(channelId, time)
@@ -290,6 +322,22 @@ func (_m *PostStore) GetPostsCreatedAt(channelId string, time int64) store.Store
 	return r0
 }
 
+// GetPostsForChannelExport provides a mock function with given fields: channelId, afterCreateAt, afterId, limit
+func (_m *PostStore) GetPostsForChannelExport(channelId string, afterCreateAt int64, afterId string, limit int) store.StoreChannel {
+	ret := _m.Called(channelId, afterCreateAt, afterId, limit)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, int64, string, int) store.StoreChannel); ok {
+		r0 = rf(channelId, afterCreateAt, afterId, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // GetPostsSince provides a mock function with given fields: channelId, time, allowFromCache
 func (_m *PostStore) GetPostsSince(channelId string, time int64, allowFromCache bool) store.StoreChannel {
 	ret := _m.Called(channelId, time, allowFromCache)
@@ -306,6 +354,38 @@ func (_m *PostStore) GetPostsSince(channelId string, time int64, allowFromCache
 	return r0
 }
 
+// GetPostsWithMessageContaining provides a mock function with given fields: term, afterId, limit
+func (_m *PostStore) GetPostsWithMessageContaining(term string, afterId string, limit int) store.StoreChannel {
+	ret := _m.Called(term, afterId, limit)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, string, int) store.StoreChannel); ok {
+		r0 = rf(term, afterId, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// GetPostsWithPropsLargerThan provides a mock function with given fields: size, afterId, limit
+func (_m *PostStore) GetPostsWithPropsLargerThan(size int, afterId string, limit int) store.StoreChannel {
+	ret := _m.Called(size, afterId, limit)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(int, string, int) store.StoreChannel); ok {
+		r0 = rf(size, afterId, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // GetSingle provides a mock function with given fields: id
 func (_m *PostStore) GetSingle(id string) store.StoreChannel {
 	ret := _m.Called(id)
@@ -375,6 +455,22 @@ func (_m *PostStore) PermanentDeleteByChannel(channelId string) store.StoreChann
 	return r0
 }
 
+// PermanentDeleteByChannelBatch provides a mock function with given fields: channelId, limit
+func (_m *PostStore) PermanentDeleteByChannelBatch(channelId string, limit int64) store.StoreChannel {
+	ret := _m.Called(channelId, limit)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, int64) store.StoreChannel); ok {
+		r0 = rf(channelId, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // PermanentDeleteByUser provides a mock function with given fields: userId
 func (_m *PostStore) PermanentDeleteByUser(userId string) store.StoreChannel {
 	ret := _m.Called(userId)
@@ -407,6 +503,38 @@ func (_m *PostStore) Save(post *model.Post) store.StoreChannel {
 	return r0
 }
 
+// SaveHistory provides a mock function with given fields: history
+func (_m *PostStore) SaveHistory(history *model.PostHistory) store.StoreChannel {
+	ret := _m.Called(history)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(*model.PostHistory) store.StoreChannel); ok {
+		r0 = rf(history)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// SaveWithWebSocketEvent provides a mock function with given fields: post
+func (_m *PostStore) SaveWithWebSocketEvent(post *model.Post) store.StoreChannel {
+	ret := _m.Called(post)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(*model.Post) store.StoreChannel); ok {
+		r0 = rf(post)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // Search provides a mock function with given fields: teamId, userId, params
 func (_m *PostStore) Search(teamId string, userId string, params *model.SearchParams) store.StoreChannel {
 	ret := _m.Called(teamId, userId, params)
@@ -13,6 +13,22 @@ type PreferenceStore struct {
 	mock.Mock
 }
 
+// CleanupChannelsBatch provides a mock function with given fields: limit
+func (_m *PreferenceStore) CleanupChannelsBatch(limit int64) store.StoreChannel {
+	ret := _m.Called(limit)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(int64) store.StoreChannel); ok {
+		r0 = rf(limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // CleanupFlagsBatch provides a mock function with given fields: limit
 func (_m *PreferenceStore) CleanupFlagsBatch(limit int64) store.StoreChannel {
 	ret := _m.Called(limit)
@@ -125,6 +141,38 @@ func (_m *PreferenceStore) GetCategory(userId string, category string) store.Sto
 	return r0
 }
 
+// GetPage provides a mock function with given fields: userId, category, offset, limit
+func (_m *PreferenceStore) GetPage(userId string, category string, offset int, limit int) store.StoreChannel {
+	ret := _m.Called(userId, category, offset, limit)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, string, int, int) store.StoreChannel); ok {
+		r0 = rf(userId, category, offset, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// GetUserIdsByCategoryAndName provides a mock function with given fields: category, name
+func (_m *PreferenceStore) GetUserIdsByCategoryAndName(category string, name string) store.StoreChannel {
+	ret := _m.Called(category, name)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, string) store.StoreChannel); ok {
+		r0 = rf(category, name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // IsFeatureEnabled provides a mock function with given fields: feature, userId
 func (_m *PreferenceStore) IsFeatureEnabled(feature string, userId string) store.StoreChannel {
 	ret := _m.Called(feature, userId)
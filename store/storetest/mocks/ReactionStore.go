@@ -45,6 +45,38 @@ func (_m *ReactionStore) DeleteAllWithEmojiName(emojiName string) store.StoreCha
 	return r0
 }
 
+// DeleteWithWebSocketEvent provides a mock function with given fields: reaction, event
+func (_m *ReactionStore) DeleteWithWebSocketEvent(reaction *model.Reaction, event *model.WebSocketOutboxEvent) store.StoreChannel {
+	ret := _m.Called(reaction, event)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(*model.Reaction, *model.WebSocketOutboxEvent) store.StoreChannel); ok {
+		r0 = rf(reaction, event)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// GetCountsForPosts provides a mock function with given fields: postIds, userId
+func (_m *ReactionStore) GetCountsForPosts(postIds []string, userId string) store.StoreChannel {
+	ret := _m.Called(postIds, userId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func([]string, string) store.StoreChannel); ok {
+		r0 = rf(postIds, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // GetForPost provides a mock function with given fields: postId, allowFromCache
 func (_m *ReactionStore) GetForPost(postId string, allowFromCache bool) store.StoreChannel {
 	ret := _m.Called(postId, allowFromCache)
@@ -61,6 +93,22 @@ func (_m *ReactionStore) GetForPost(postId string, allowFromCache bool) store.St
 	return r0
 }
 
+// GetForUser provides a mock function with given fields: userId
+func (_m *ReactionStore) GetForUser(userId string) store.StoreChannel {
+	ret := _m.Called(userId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string) store.StoreChannel); ok {
+		r0 = rf(userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // PermanentDeleteBatch provides a mock function with given fields: endTime, limit
 func (_m *ReactionStore) PermanentDeleteBatch(endTime int64, limit int64) store.StoreChannel {
 	ret := _m.Called(endTime, limit)
@@ -92,3 +140,19 @@ func (_m *ReactionStore) Save(reaction *model.Reaction) store.StoreChannel {
 
 	return r0
 }
+
+// SaveWithWebSocketEvent provides a mock function with given fields: reaction, event
+func (_m *ReactionStore) SaveWithWebSocketEvent(reaction *model.Reaction, event *model.WebSocketOutboxEvent) store.StoreChannel {
+	ret := _m.Called(reaction, event)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(*model.Reaction, *model.WebSocketOutboxEvent) store.StoreChannel); ok {
+		r0 = rf(reaction, event)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
@@ -29,11 +29,6 @@ func (_m *SessionStore) AnalyticsSessionCount() store.StoreChannel {
 	return r0
 }
 
-// Cleanup provides a mock function with given fields: expiryTime, batchSize
-func (_m *SessionStore) Cleanup(expiryTime int64, batchSize int64) {
-	_m.Called(expiryTime, batchSize)
-}
-
 // Get provides a mock function with given fields: sessionIdOrToken
 func (_m *SessionStore) Get(sessionIdOrToken string) store.StoreChannel {
 	ret := _m.Called(sessionIdOrToken)
@@ -50,6 +45,22 @@ func (_m *SessionStore) Get(sessionIdOrToken string) store.StoreChannel {
 	return r0
 }
 
+// GetImpersonationSessions provides a mock function with given fields: impersonatorSessionId
+func (_m *SessionStore) GetImpersonationSessions(impersonatorSessionId string) store.StoreChannel {
+	ret := _m.Called(impersonatorSessionId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string) store.StoreChannel); ok {
+		r0 = rf(impersonatorSessionId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // GetSessions provides a mock function with given fields: userId
 func (_m *SessionStore) GetSessions(userId string) store.StoreChannel {
 	ret := _m.Called(userId)
@@ -82,6 +93,38 @@ func (_m *SessionStore) GetSessionsWithActiveDeviceIds(userId string) store.Stor
 	return r0
 }
 
+// PermanentDeleteExpiredBatch provides a mock function with given fields: expiredBefore, limit
+func (_m *SessionStore) PermanentDeleteExpiredBatch(expiredBefore int64, limit int64) store.StoreChannel {
+	ret := _m.Called(expiredBefore, limit)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(int64, int64) store.StoreChannel); ok {
+		r0 = rf(expiredBefore, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// PermanentDeleteOrphanedBatch provides a mock function with given fields: limit
+func (_m *SessionStore) PermanentDeleteOrphanedBatch(limit int64) store.StoreChannel {
+	ret := _m.Called(limit)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(int64) store.StoreChannel); ok {
+		r0 = rf(limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // PermanentDeleteSessionsByUser provides a mock function with given fields: teamId
 func (_m *SessionStore) PermanentDeleteSessionsByUser(teamId string) store.StoreChannel {
 	ret := _m.Called(teamId)
@@ -178,6 +221,38 @@ func (_m *SessionStore) UpdateLastActivityAt(sessionId string, time int64) store
 	return r0
 }
 
+// UpdateLastActivityAtBatch provides a mock function with given fields: lastActivityAt
+func (_m *SessionStore) UpdateLastActivityAtBatch(lastActivityAt map[string]int64) store.StoreChannel {
+	ret := _m.Called(lastActivityAt)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(map[string]int64) store.StoreChannel); ok {
+		r0 = rf(lastActivityAt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// UpdateProps provides a mock function with given fields: sessionId, props
+func (_m *SessionStore) UpdateProps(sessionId string, props model.StringMap) store.StoreChannel {
+	ret := _m.Called(sessionId, props)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, model.StringMap) store.StoreChannel); ok {
+		r0 = rf(sessionId, props)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // UpdateRoles provides a mock function with given fields: userId, roles
 func (_m *SessionStore) UpdateRoles(userId string, roles string) store.StoreChannel {
 	ret := _m.Called(userId, roles)
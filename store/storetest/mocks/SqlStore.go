@@ -8,6 +8,7 @@ import gorp "github.com/mattermost/gorp"
 import mock "github.com/stretchr/testify/mock"
 
 import store "github.com/mattermost/mattermost-server/store"
+import utils "github.com/mattermost/mattermost-server/utils"
 
 // SqlStore is an autogenerated mock type for the SqlStore type
 type SqlStore struct {
@@ -289,6 +290,22 @@ func (_m *SqlStore) GetAllConns() []*gorp.DbMap {
 	return r0
 }
 
+// GetCacheProvider provides a mock function with given fields:
+func (_m *SqlStore) GetCacheProvider() *utils.CacheProvider {
+	ret := _m.Called()
+
+	var r0 *utils.CacheProvider
+	if rf, ok := ret.Get(0).(func() *utils.CacheProvider); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*utils.CacheProvider)
+		}
+	}
+
+	return r0
+}
+
 // GetCurrentSchemaVersion provides a mock function with given fields:
 func (_m *SqlStore) GetCurrentSchemaVersion() string {
 	ret := _m.Called()
@@ -365,6 +382,34 @@ func (_m *SqlStore) GetSearchReplica() *gorp.DbMap {
 	return r0
 }
 
+// IsFullUnicodeSupported provides a mock function with given fields:
+func (_m *SqlStore) IsFullUnicodeSupported() bool {
+	ret := _m.Called()
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// IsMasterHealthy provides a mock function with given fields:
+func (_m *SqlStore) IsMasterHealthy() bool {
+	ret := _m.Called()
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // Job provides a mock function with given fields:
 func (_m *SqlStore) Job() store.JobStore {
 	ret := _m.Called()
@@ -12,6 +12,22 @@ type Store struct {
 	mock.Mock
 }
 
+// AnalyticsDaily provides a mock function with given fields:
+func (_m *Store) AnalyticsDaily() store.AnalyticsDailyStore {
+	ret := _m.Called()
+
+	var r0 store.AnalyticsDailyStore
+	if rf, ok := ret.Get(0).(func() store.AnalyticsDailyStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.AnalyticsDailyStore)
+		}
+	}
+
+	return r0
+}
+
 // Audit provides a mock function with given fields:
 func (_m *Store) Audit() store.AuditStore {
 	ret := _m.Called()
@@ -28,6 +44,22 @@ func (_m *Store) Audit() store.AuditStore {
 	return r0
 }
 
+// BlockedDomain provides a mock function with given fields:
+func (_m *Store) BlockedDomain() store.BlockedDomainStore {
+	ret := _m.Called()
+
+	var r0 store.BlockedDomainStore
+	if rf, ok := ret.Get(0).(func() store.BlockedDomainStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.BlockedDomainStore)
+		}
+	}
+
+	return r0
+}
+
 // Channel provides a mock function with given fields:
 func (_m *Store) Channel() store.ChannelStore {
 	ret := _m.Called()
@@ -129,6 +161,38 @@ func (_m *Store) Compliance() store.ComplianceStore {
 	return r0
 }
 
+// ConfigurationHistory provides a mock function with given fields:
+func (_m *Store) ConfigurationHistory() store.ConfigurationHistoryStore {
+	ret := _m.Called()
+
+	var r0 store.ConfigurationHistoryStore
+	if rf, ok := ret.Get(0).(func() store.ConfigurationHistoryStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.ConfigurationHistoryStore)
+		}
+	}
+
+	return r0
+}
+
+// CustomGroup provides a mock function with given fields:
+func (_m *Store) CustomGroup() store.CustomGroupStore {
+	ret := _m.Called()
+
+	var r0 store.CustomGroupStore
+	if rf, ok := ret.Get(0).(func() store.CustomGroupStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.CustomGroupStore)
+		}
+	}
+
+	return r0
+}
+
 // DropAllTables provides a mock function with given fields:
 func (_m *Store) DropAllTables() {
 	_m.Called()
@@ -166,6 +230,96 @@ func (_m *Store) FileInfo() store.FileInfoStore {
 	return r0
 }
 
+// FilePublicLink provides a mock function with given fields:
+func (_m *Store) FilePublicLink() store.FilePublicLinkStore {
+	ret := _m.Called()
+
+	var r0 store.FilePublicLinkStore
+	if rf, ok := ret.Get(0).(func() store.FilePublicLinkStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.FilePublicLinkStore)
+		}
+	}
+
+	return r0
+}
+
+// FileStorageUsage provides a mock function with given fields:
+func (_m *Store) FileStorageUsage() store.FileStorageUsageStore {
+	ret := _m.Called()
+
+	var r0 store.FileStorageUsageStore
+	if rf, ok := ret.Get(0).(func() store.FileStorageUsageStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.FileStorageUsageStore)
+		}
+	}
+
+	return r0
+}
+
+// InviteLink provides a mock function with given fields:
+func (_m *Store) InviteLink() store.InviteLinkStore {
+	ret := _m.Called()
+
+	var r0 store.InviteLinkStore
+	if rf, ok := ret.Get(0).(func() store.InviteLinkStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.InviteLinkStore)
+		}
+	}
+
+	return r0
+}
+
+// IsFullUnicodeSupported provides a mock function with given fields:
+func (_m *Store) IsFullUnicodeSupported() bool {
+	ret := _m.Called()
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// IsMasterHealthy provides a mock function with given fields:
+func (_m *Store) IsMasterHealthy() bool {
+	ret := _m.Called()
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// IsReadOnly provides a mock function with given fields:
+func (_m *Store) IsReadOnly() bool {
+	ret := _m.Called()
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // Job provides a mock function with given fields:
 func (_m *Store) Job() store.JobStore {
 	ret := _m.Called()
@@ -251,6 +405,22 @@ func (_m *Store) Post() store.PostStore {
 	return r0
 }
 
+// PostIdempotencyKey provides a mock function with given fields:
+func (_m *Store) PostIdempotencyKey() store.PostIdempotencyKeyStore {
+	ret := _m.Called()
+
+	var r0 store.PostIdempotencyKeyStore
+	if rf, ok := ret.Get(0).(func() store.PostIdempotencyKeyStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.PostIdempotencyKeyStore)
+		}
+	}
+
+	return r0
+}
+
 // Preference provides a mock function with given fields:
 func (_m *Store) Preference() store.PreferenceStore {
 	ret := _m.Called()
@@ -267,6 +437,22 @@ func (_m *Store) Preference() store.PreferenceStore {
 	return r0
 }
 
+// ProvisioningToken provides a mock function with given fields:
+func (_m *Store) ProvisioningToken() store.ProvisioningTokenStore {
+	ret := _m.Called()
+
+	var r0 store.ProvisioningTokenStore
+	if rf, ok := ret.Get(0).(func() store.ProvisioningTokenStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.ProvisioningTokenStore)
+		}
+	}
+
+	return r0
+}
+
 // Reaction provides a mock function with given fields:
 func (_m *Store) Reaction() store.ReactionStore {
 	ret := _m.Called()
@@ -283,6 +469,22 @@ func (_m *Store) Reaction() store.ReactionStore {
 	return r0
 }
 
+// RetentionPolicy provides a mock function with given fields:
+func (_m *Store) RetentionPolicy() store.RetentionPolicyStore {
+	ret := _m.Called()
+
+	var r0 store.RetentionPolicyStore
+	if rf, ok := ret.Get(0).(func() store.RetentionPolicyStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.RetentionPolicyStore)
+		}
+	}
+
+	return r0
+}
+
 // Role provides a mock function with given fields:
 func (_m *Store) Role() store.RoleStore {
 	ret := _m.Called()
@@ -315,6 +517,27 @@ func (_m *Store) Session() store.SessionStore {
 	return r0
 }
 
+// SetReadOnly provides a mock function with given fields: readOnly
+func (_m *Store) SetReadOnly(readOnly bool) {
+	_m.Called(readOnly)
+}
+
+// ShortPermalink provides a mock function with given fields:
+func (_m *Store) ShortPermalink() store.ShortPermalinkStore {
+	ret := _m.Called()
+
+	var r0 store.ShortPermalinkStore
+	if rf, ok := ret.Get(0).(func() store.ShortPermalinkStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.ShortPermalinkStore)
+		}
+	}
+
+	return r0
+}
+
 // Status provides a mock function with given fields:
 func (_m *Store) Status() store.StatusStore {
 	ret := _m.Called()
@@ -363,6 +586,70 @@ func (_m *Store) Team() store.TeamStore {
 	return r0
 }
 
+// TeamInvitation provides a mock function with given fields:
+func (_m *Store) TeamInvitation() store.TeamInvitationStore {
+	ret := _m.Called()
+
+	var r0 store.TeamInvitationStore
+	if rf, ok := ret.Get(0).(func() store.TeamInvitationStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.TeamInvitationStore)
+		}
+	}
+
+	return r0
+}
+
+// TeamTemplate provides a mock function with given fields:
+func (_m *Store) TeamTemplate() store.TeamTemplateStore {
+	ret := _m.Called()
+
+	var r0 store.TeamTemplateStore
+	if rf, ok := ret.Get(0).(func() store.TeamTemplateStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.TeamTemplateStore)
+		}
+	}
+
+	return r0
+}
+
+// TermsOfService provides a mock function with given fields:
+func (_m *Store) TermsOfService() store.TermsOfServiceStore {
+	ret := _m.Called()
+
+	var r0 store.TermsOfServiceStore
+	if rf, ok := ret.Get(0).(func() store.TermsOfServiceStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.TermsOfServiceStore)
+		}
+	}
+
+	return r0
+}
+
+// Thread provides a mock function with given fields:
+func (_m *Store) Thread() store.ThreadStore {
+	ret := _m.Called()
+
+	var r0 store.ThreadStore
+	if rf, ok := ret.Get(0).(func() store.ThreadStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.ThreadStore)
+		}
+	}
+
+	return r0
+}
+
 // Token provides a mock function with given fields:
 func (_m *Store) Token() store.TokenStore {
 	ret := _m.Called()
@@ -453,6 +740,38 @@ func (_m *Store) UserAccessToken() store.UserAccessTokenStore {
 	return r0
 }
 
+// UserAttribute provides a mock function with given fields:
+func (_m *Store) UserAttribute() store.UserAttributeStore {
+	ret := _m.Called()
+
+	var r0 store.UserAttributeStore
+	if rf, ok := ret.Get(0).(func() store.UserAttributeStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.UserAttributeStore)
+		}
+	}
+
+	return r0
+}
+
+// WebSocketOutbox provides a mock function with given fields:
+func (_m *Store) WebSocketOutbox() store.WebSocketOutboxStore {
+	ret := _m.Called()
+
+	var r0 store.WebSocketOutboxStore
+	if rf, ok := ret.Get(0).(func() store.WebSocketOutboxStore); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.WebSocketOutboxStore)
+		}
+	}
+
+	return r0
+}
+
 // Webhook provides a mock function with given fields:
 func (_m *Store) Webhook() store.WebhookStore {
 	ret := _m.Called()
@@ -77,6 +77,22 @@ func (_m *TeamStore) GetAll() store.StoreChannel {
 	return r0
 }
 
+// GetAllMemberCounts provides a mock function with given fields:
+func (_m *TeamStore) GetAllMemberCounts() store.StoreChannel {
+	ret := _m.Called()
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func() store.StoreChannel); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // GetAllPage provides a mock function with given fields: offset, limit
 func (_m *TeamStore) GetAllPage(offset int, limit int) store.StoreChannel {
 	ret := _m.Called(offset, limit)
@@ -189,6 +205,54 @@ func (_m *TeamStore) GetChannelUnreadsForTeam(teamId string, userId string) stor
 	return r0
 }
 
+// GetDataCounts provides a mock function with given fields: teamId
+func (_m *TeamStore) GetDataCounts(teamId string) store.StoreChannel {
+	ret := _m.Called(teamId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string) store.StoreChannel); ok {
+		r0 = rf(teamId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// GetDefaultChannels provides a mock function with given fields: teamId
+func (_m *TeamStore) GetDefaultChannels(teamId string) store.StoreChannel {
+	ret := _m.Called(teamId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string) store.StoreChannel); ok {
+		r0 = rf(teamId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// GetFilteredWords provides a mock function with given fields: teamId
+func (_m *TeamStore) GetFilteredWords(teamId string) store.StoreChannel {
+	ret := _m.Called(teamId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string) store.StoreChannel); ok {
+		r0 = rf(teamId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // GetMember provides a mock function with given fields: teamId, userId
 func (_m *TeamStore) GetMember(teamId string, userId string) store.StoreChannel {
 	ret := _m.Called(teamId, userId)
@@ -301,6 +365,22 @@ func (_m *TeamStore) PermanentDelete(teamId string) store.StoreChannel {
 	return r0
 }
 
+// ReconcileMemberCounts provides a mock function with given fields: afterTeamId, limit
+func (_m *TeamStore) ReconcileMemberCounts(afterTeamId string, limit int) store.StoreChannel {
+	ret := _m.Called(afterTeamId, limit)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, int) store.StoreChannel); ok {
+		r0 = rf(afterTeamId, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // RemoveAllMembersByTeam provides a mock function with given fields: teamId
 func (_m *TeamStore) RemoveAllMembersByTeam(teamId string) store.StoreChannel {
 	ret := _m.Called(teamId)
@@ -445,6 +525,22 @@ func (_m *TeamStore) Update(team *model.Team) store.StoreChannel {
 	return r0
 }
 
+// UpdateDefaultChannels provides a mock function with given fields: teamId, channelIds
+func (_m *TeamStore) UpdateDefaultChannels(teamId string, channelIds []string) store.StoreChannel {
+	ret := _m.Called(teamId, channelIds)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, []string) store.StoreChannel); ok {
+		r0 = rf(teamId, channelIds)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // UpdateDisplayName provides a mock function with given fields: name, teamId
 func (_m *TeamStore) UpdateDisplayName(name string, teamId string) store.StoreChannel {
 	ret := _m.Called(name, teamId)
@@ -461,6 +557,22 @@ func (_m *TeamStore) UpdateDisplayName(name string, teamId string) store.StoreCh
 	return r0
 }
 
+// UpdateFilteredWords provides a mock function with given fields: teamId, words
+func (_m *TeamStore) UpdateFilteredWords(teamId string, words []string) store.StoreChannel {
+	ret := _m.Called(teamId, words)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, []string) store.StoreChannel); ok {
+		r0 = rf(teamId, words)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // UpdateLastTeamIconUpdate provides a mock function with given fields: teamId, curTime
 func (_m *TeamStore) UpdateLastTeamIconUpdate(teamId string, curTime int64) store.StoreChannel {
 	ret := _m.Called(teamId, curTime)
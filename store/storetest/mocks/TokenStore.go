@@ -34,6 +34,22 @@ func (_m *TokenStore) Delete(token string) store.StoreChannel {
 	return r0
 }
 
+// GetAllTokensByType provides a mock function with given fields: tokenType
+func (_m *TokenStore) GetAllTokensByType(tokenType string) store.StoreChannel {
+	ret := _m.Called(tokenType)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string) store.StoreChannel); ok {
+		r0 = rf(tokenType)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // GetByToken provides a mock function with given fields: token
 func (_m *TokenStore) GetByToken(token string) store.StoreChannel {
 	ret := _m.Called(token)
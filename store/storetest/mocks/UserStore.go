@@ -29,6 +29,22 @@ func (_m *UserStore) AnalyticsActiveCount(time int64) store.StoreChannel {
 	return r0
 }
 
+// AnalyticsBotCount provides a mock function with given fields:
+func (_m *UserStore) AnalyticsBotCount() store.StoreChannel {
+	ret := _m.Called()
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func() store.StoreChannel); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // AnalyticsGetInactiveUsersCount provides a mock function with given fields:
 func (_m *UserStore) AnalyticsGetInactiveUsersCount() store.StoreChannel {
 	ret := _m.Called()
@@ -82,6 +98,70 @@ func (_m *UserStore) ClearCaches() {
 	_m.Called()
 }
 
+// CountUsingAuthService provides a mock function with given fields: authService, authData
+func (_m *UserStore) CountUsingAuthService(authService string, authData string) store.StoreChannel {
+	ret := _m.Called(authService, authData)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, string) store.StoreChannel); ok {
+		r0 = rf(authService, authData)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// CountWithFilter provides a mock function with given fields: filter
+func (_m *UserStore) CountWithFilter(filter *model.UserFilter) store.StoreChannel {
+	ret := _m.Called(filter)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(*model.UserFilter) store.StoreChannel); ok {
+		r0 = rf(filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// DeleteUsernameHistory provides a mock function with given fields: userId
+func (_m *UserStore) DeleteUsernameHistory(userId string) store.StoreChannel {
+	ret := _m.Called(userId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string) store.StoreChannel); ok {
+		r0 = rf(userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// DemoteUserToGuest provides a mock function with given fields: userId
+func (_m *UserStore) DemoteUserToGuest(userId string) store.StoreChannel {
+	ret := _m.Called(userId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string) store.StoreChannel); ok {
+		r0 = rf(userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // Get provides a mock function with given fields: id
 func (_m *UserStore) Get(id string) store.StoreChannel {
 	ret := _m.Called(id)
@@ -162,6 +242,22 @@ func (_m *UserStore) GetAllUsingAuthService(authService string) store.StoreChann
 	return r0
 }
 
+// GetBotsInChannel provides a mock function with given fields: channelId
+func (_m *UserStore) GetBotsInChannel(channelId string) store.StoreChannel {
+	ret := _m.Called(channelId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string) store.StoreChannel); ok {
+		r0 = rf(channelId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // GetByAuth provides a mock function with given fields: authData, authService
 func (_m *UserStore) GetByAuth(authData *string, authService string) store.StoreChannel {
 	ret := _m.Called(authData, authService)
@@ -194,6 +290,22 @@ func (_m *UserStore) GetByEmail(email string) store.StoreChannel {
 	return r0
 }
 
+// GetByFullNames provides a mock function with given fields: fullNames
+func (_m *UserStore) GetByFullNames(fullNames []string) store.StoreChannel {
+	ret := _m.Called(fullNames)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func([]string) store.StoreChannel); ok {
+		r0 = rf(fullNames)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // GetByUsername provides a mock function with given fields: username
 func (_m *UserStore) GetByUsername(username string) store.StoreChannel {
 	ret := _m.Called(username)
@@ -210,6 +322,22 @@ func (_m *UserStore) GetByUsername(username string) store.StoreChannel {
 	return r0
 }
 
+// GetDueForDeactivation provides a mock function with given fields: deactivateBefore
+func (_m *UserStore) GetDueForDeactivation(deactivateBefore int64) store.StoreChannel {
+	ret := _m.Called(deactivateBefore)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(int64) store.StoreChannel); ok {
+		r0 = rf(deactivateBefore)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // GetEtagForAllProfiles provides a mock function with given fields:
 func (_m *UserStore) GetEtagForAllProfiles() store.StoreChannel {
 	ret := _m.Called()
@@ -274,6 +402,22 @@ func (_m *UserStore) GetForLogin(loginId string, allowSignInWithUsername bool, a
 	return r0
 }
 
+// GetIdsSharingChannelsWithUser provides a mock function with given fields: userId, userIds
+func (_m *UserStore) GetIdsSharingChannelsWithUser(userId string, userIds []string) store.StoreChannel {
+	ret := _m.Called(userId, userIds)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, []string) store.StoreChannel); ok {
+		r0 = rf(userId, userIds)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // GetNewUsersForTeam provides a mock function with given fields: teamId, offset, limit
 func (_m *UserStore) GetNewUsersForTeam(teamId string, offset int, limit int) store.StoreChannel {
 	ret := _m.Called(teamId, offset, limit)
@@ -370,6 +514,22 @@ func (_m *UserStore) GetProfilesInChannelByStatus(channelId string, offset int,
 	return r0
 }
 
+// GetProfilesInUserChannels provides a mock function with given fields: userId, teamId, offset, limit
+func (_m *UserStore) GetProfilesInUserChannels(userId string, teamId string, offset int, limit int) store.StoreChannel {
+	ret := _m.Called(userId, teamId, offset, limit)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, string, int, int) store.StoreChannel); ok {
+		r0 = rf(userId, teamId, offset, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // GetProfilesNotInChannel provides a mock function with given fields: teamId, channelId, offset, limit
 func (_m *UserStore) GetProfilesNotInChannel(teamId string, channelId string, offset int, limit int) store.StoreChannel {
 	ret := _m.Called(teamId, channelId, offset, limit)
@@ -402,6 +562,22 @@ func (_m *UserStore) GetProfilesNotInTeam(teamId string, offset int, limit int)
 	return r0
 }
 
+// GetProfilesWithFilter provides a mock function with given fields: filter, offset, limit
+func (_m *UserStore) GetProfilesWithFilter(filter *model.UserFilter, offset int, limit int) store.StoreChannel {
+	ret := _m.Called(filter, offset, limit)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(*model.UserFilter, int, int) store.StoreChannel); ok {
+		r0 = rf(filter, offset, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // GetProfilesWithoutTeam provides a mock function with given fields: offset, limit
 func (_m *UserStore) GetProfilesWithoutTeam(offset int, limit int) store.StoreChannel {
 	ret := _m.Called(offset, limit)
@@ -498,6 +674,38 @@ func (_m *UserStore) GetUnreadCountForChannel(userId string, channelId string) s
 	return r0
 }
 
+// GetUsernameHistoryByUsername provides a mock function with given fields: username
+func (_m *UserStore) GetUsernameHistoryByUsername(username string) store.StoreChannel {
+	ret := _m.Called(username)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string) store.StoreChannel); ok {
+		r0 = rf(username)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
+// GetUsingAuthService provides a mock function with given fields: authService, authData, offset, limit
+func (_m *UserStore) GetUsingAuthService(authService string, authData string, offset int, limit int) store.StoreChannel {
+	ret := _m.Called(authService, authData, offset, limit)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, string, int, int) store.StoreChannel); ok {
+		r0 = rf(authService, authData, offset, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // InvalidatProfileCacheForUser provides a mock function with given fields: userId
 func (_m *UserStore) InvalidatProfileCacheForUser(userId string) {
 	_m.Called(userId)
@@ -529,6 +737,22 @@ func (_m *UserStore) PermanentDelete(userId string) store.StoreChannel {
 	return r0
 }
 
+// PromoteGuestToUser provides a mock function with given fields: userId
+func (_m *UserStore) PromoteGuestToUser(userId string) store.StoreChannel {
+	ret := _m.Called(userId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string) store.StoreChannel); ok {
+		r0 = rf(userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // Save provides a mock function with given fields: user
 func (_m *UserStore) Save(user *model.User) store.StoreChannel {
 	ret := _m.Called(user)
@@ -545,6 +769,22 @@ func (_m *UserStore) Save(user *model.User) store.StoreChannel {
 	return r0
 }
 
+// SaveUsernameHistory provides a mock function with given fields: history, maxPerUser
+func (_m *UserStore) SaveUsernameHistory(history *model.UsernameHistory, maxPerUser int) store.StoreChannel {
+	ret := _m.Called(history, maxPerUser)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(*model.UsernameHistory, int) store.StoreChannel); ok {
+		r0 = rf(history, maxPerUser)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // Search provides a mock function with given fields: teamId, term, options
 func (_m *UserStore) Search(teamId string, term string, options map[string]bool) store.StoreChannel {
 	ret := _m.Called(teamId, term, options)
@@ -577,6 +817,22 @@ func (_m *UserStore) SearchInChannel(channelId string, term string, options map[
 	return r0
 }
 
+// SearchInUserChannels provides a mock function with given fields: userId, teamId, term, options
+func (_m *UserStore) SearchInUserChannels(userId string, teamId string, term string, options map[string]bool) store.StoreChannel {
+	ret := _m.Called(userId, teamId, term, options)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, string, string, map[string]bool) store.StoreChannel); ok {
+		r0 = rf(userId, teamId, term, options)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // SearchNotInChannel provides a mock function with given fields: teamId, channelId, term, options
 func (_m *UserStore) SearchNotInChannel(teamId string, channelId string, term string, options map[string]bool) store.StoreChannel {
 	ret := _m.Called(teamId, channelId, term, options)
@@ -657,6 +913,22 @@ func (_m *UserStore) UpdateAuthData(userId string, service string, authData *str
 	return r0
 }
 
+// UpdateDeactivateAt provides a mock function with given fields: userId, deactivateAt
+func (_m *UserStore) UpdateDeactivateAt(userId string, deactivateAt int64) store.StoreChannel {
+	ret := _m.Called(userId, deactivateAt)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, int64) store.StoreChannel); ok {
+		r0 = rf(userId, deactivateAt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // UpdateFailedPasswordAttempts provides a mock function with given fields: userId, attempts
 func (_m *UserStore) UpdateFailedPasswordAttempts(userId string, attempts int) store.StoreChannel {
 	ret := _m.Called(userId, attempts)
@@ -689,6 +961,22 @@ func (_m *UserStore) UpdateLastPictureUpdate(userId string) store.StoreChannel {
 	return r0
 }
 
+// UpdateLastSeenAt provides a mock function with given fields: userId, lastSeenAt
+func (_m *UserStore) UpdateLastSeenAt(userId string, lastSeenAt int64) store.StoreChannel {
+	ret := _m.Called(userId, lastSeenAt)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string, int64) store.StoreChannel); ok {
+		r0 = rf(userId, lastSeenAt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // UpdateMfaActive provides a mock function with given fields: userId, active
 func (_m *UserStore) UpdateMfaActive(userId string, active bool) store.StoreChannel {
 	ret := _m.Called(userId, active)
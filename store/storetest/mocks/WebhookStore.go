@@ -231,6 +231,22 @@ func (_m *WebhookStore) PermanentDeleteIncomingByChannel(channelId string) store
 	return r0
 }
 
+// PermanentDeleteIncomingByTeam provides a mock function with given fields: teamId
+func (_m *WebhookStore) PermanentDeleteIncomingByTeam(teamId string) store.StoreChannel {
+	ret := _m.Called(teamId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string) store.StoreChannel); ok {
+		r0 = rf(teamId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // PermanentDeleteIncomingByUser provides a mock function with given fields: userId
 func (_m *WebhookStore) PermanentDeleteIncomingByUser(userId string) store.StoreChannel {
 	ret := _m.Called(userId)
@@ -263,6 +279,22 @@ func (_m *WebhookStore) PermanentDeleteOutgoingByChannel(channelId string) store
 	return r0
 }
 
+// PermanentDeleteOutgoingByTeam provides a mock function with given fields: teamId
+func (_m *WebhookStore) PermanentDeleteOutgoingByTeam(teamId string) store.StoreChannel {
+	ret := _m.Called(teamId)
+
+	var r0 store.StoreChannel
+	if rf, ok := ret.Get(0).(func(string) store.StoreChannel); ok {
+		r0 = rf(teamId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(store.StoreChannel)
+		}
+	}
+
+	return r0
+}
+
 // PermanentDeleteOutgoingByUser provides a mock function with given fields: userId
 func (_m *WebhookStore) PermanentDeleteOutgoingByUser(userId string) store.StoreChannel {
 	ret := _m.Called(userId)
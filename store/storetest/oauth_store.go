@@ -4,6 +4,7 @@
 package storetest
 
 import (
+	"net/http"
 	"testing"
 
 	"github.com/mattermost/mattermost-server/model"
@@ -178,6 +179,7 @@ func testOAuthUpdateAccessData(t *testing.T, ss store.Store) {
 
 	// Should update fine
 	a1.RedirectUri = "http://example.com"
+	a1.PreviousRefreshToken = refreshToken
 	if result := <-ss.OAuth().UpdateAccessData(&a1); result.Err != nil {
 		t.Fatal(result.Err)
 	} else {
@@ -186,6 +188,22 @@ func testOAuthUpdateAccessData(t *testing.T, ss store.Store) {
 			t.Fatal("refresh tokens didn't match")
 		}
 	}
+
+	// The replaced refresh token can still be found
+	if result := <-ss.OAuth().GetAccessDataByPreviousRefreshToken(refreshToken); result.Err != nil {
+		t.Fatal(result.Err)
+	} else {
+		ra1 := result.Data.(*model.AccessData)
+		if ra1.RefreshToken != a1.RefreshToken {
+			t.Fatal("refresh tokens didn't match")
+		}
+	}
+
+	if result := <-ss.OAuth().GetAccessDataByPreviousRefreshToken(model.NewId()); result.Err == nil {
+		t.Fatal("Should have failed. There is no data with that previous refresh token")
+	} else if result.Err.StatusCode != http.StatusNotFound {
+		t.Fatal("Should have returned not found")
+	}
 }
 
 func testOAuthStoreGetAccessData(t *testing.T, ss store.Store) {
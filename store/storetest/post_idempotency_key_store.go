@@ -0,0 +1,82 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package storetest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func TestPostIdempotencyKeyStore(t *testing.T, ss store.Store) {
+	t.Run("Reserve", func(t *testing.T) { testPostIdempotencyKeyStoreReserve(t, ss) })
+	t.Run("SetPostIdAndDelete", func(t *testing.T) { testPostIdempotencyKeyStoreSetPostIdAndDelete(t, ss) })
+	t.Run("PermanentDeleteBatch", func(t *testing.T) { testPostIdempotencyKeyStorePermanentDeleteBatch(t, ss) })
+}
+
+func testPostIdempotencyKeyStoreReserve(t *testing.T, ss store.Store) {
+	userId := model.NewId()
+	key := model.NewId()
+
+	reserved := store.Must(ss.PostIdempotencyKey().Reserve(&model.PostIdempotencyKey{UserId: userId, IdempotencyKey: key, CreateAt: 1000}, 0)).(bool)
+	assert.True(t, reserved)
+
+	reserved = store.Must(ss.PostIdempotencyKey().Reserve(&model.PostIdempotencyKey{UserId: userId, IdempotencyKey: key, CreateAt: 2000}, 1000)).(bool)
+	assert.False(t, reserved, "a key that hasn't expired shouldn't be reserved again")
+
+	reserved = store.Must(ss.PostIdempotencyKey().Reserve(&model.PostIdempotencyKey{UserId: model.NewId(), IdempotencyKey: key, CreateAt: 2000}, 1000)).(bool)
+	assert.True(t, reserved, "keys should be separate for each user")
+
+	reserved = store.Must(ss.PostIdempotencyKey().Reserve(&model.PostIdempotencyKey{UserId: userId, IdempotencyKey: key, CreateAt: 3000}, 1001)).(bool)
+	assert.True(t, reserved, "an expired key should be replaced")
+
+	saved := store.Must(ss.PostIdempotencyKey().Get(userId, key)).(*model.PostIdempotencyKey)
+	assert.Equal(t, int64(3000), saved.CreateAt)
+	assert.Equal(t, "", saved.PostId)
+
+	result := <-ss.PostIdempotencyKey().Reserve(&model.PostIdempotencyKey{UserId: userId, IdempotencyKey: ""}, 0)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "model.post_idempotency_key.is_valid.idempotency_key.app_error", result.Err.Id)
+}
+
+func testPostIdempotencyKeyStoreSetPostIdAndDelete(t *testing.T, ss store.Store) {
+	userId := model.NewId()
+	key := model.NewId()
+	postId := model.NewId()
+
+	store.Must(ss.PostIdempotencyKey().Reserve(&model.PostIdempotencyKey{UserId: userId, IdempotencyKey: key}, 0))
+	store.Must(ss.PostIdempotencyKey().SetPostId(userId, key, postId))
+
+	saved := store.Must(ss.PostIdempotencyKey().Get(userId, key)).(*model.PostIdempotencyKey)
+	assert.Equal(t, postId, saved.PostId)
+
+	store.Must(ss.PostIdempotencyKey().Delete(userId, key))
+
+	result := <-ss.PostIdempotencyKey().Get(userId, key)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+}
+
+func testPostIdempotencyKeyStorePermanentDeleteBatch(t *testing.T, ss store.Store) {
+	userId := model.NewId()
+	oldKey := model.NewId()
+	newKey := model.NewId()
+
+	store.Must(ss.PostIdempotencyKey().Reserve(&model.PostIdempotencyKey{UserId: userId, IdempotencyKey: oldKey, CreateAt: 1000}, 0))
+	store.Must(ss.PostIdempotencyKey().Reserve(&model.PostIdempotencyKey{UserId: userId, IdempotencyKey: newKey, CreateAt: 3000}, 0))
+
+	deleted := store.Must(ss.PostIdempotencyKey().PermanentDeleteBatch(2000, 10000)).(int64)
+	assert.True(t, deleted >= 1)
+
+	result := <-ss.PostIdempotencyKey().Get(userId, oldKey)
+	require.NotNil(t, result.Err)
+	store.Must(ss.PostIdempotencyKey().Get(userId, newKey))
+
+	store.Must(ss.PostIdempotencyKey().PermanentDeleteBatch(4000, 10000))
+}
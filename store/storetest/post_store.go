@@ -13,6 +13,7 @@ import (
 	"github.com/mattermost/mattermost-server/store"
 	"github.com/mattermost/mattermost-server/utils"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPostStore(t *testing.T, ss store.Store) {
@@ -39,10 +40,17 @@ func TestPostStore(t *testing.T, ss store.Store) {
 	t.Run("GetPostsCreatedAt", func(t *testing.T) { testPostStoreGetPostsCreatedAt(t, ss) })
 	t.Run("Overwrite", func(t *testing.T) { testPostStoreOverwrite(t, ss) })
 	t.Run("GetPostsByIds", func(t *testing.T) { testPostStoreGetPostsByIds(t, ss) })
+	t.Run("GetPostsByUser", func(t *testing.T) { testPostStoreGetPostsByUser(t, ss) })
+	t.Run("GetPostsWithMessageContaining", func(t *testing.T) { testPostStoreGetPostsWithMessageContaining(t, ss) })
+	t.Run("GetPostsWithPropsLargerThan", func(t *testing.T) { testPostStoreGetPostsWithPropsLargerThan(t, ss) })
+	t.Run("GetPostsForChannelExport", func(t *testing.T) { testPostStoreGetPostsForChannelExport(t, ss) })
 	t.Run("GetPostsBatchForIndexing", func(t *testing.T) { testPostStoreGetPostsBatchForIndexing(t, ss) })
 	t.Run("PermanentDeleteBatch", func(t *testing.T) { testPostStorePermanentDeleteBatch(t, ss) })
 	t.Run("GetOldest", func(t *testing.T) { testPostStoreGetOldest(t, ss) })
 	t.Run("TestGetMaxPostSize", func(t *testing.T) { testGetMaxPostSize(t, ss) })
+	t.Run("History", func(t *testing.T) { testPostStoreHistory(t, ss) })
+	t.Run("PermanentDeleteHistory", func(t *testing.T) { testPostStorePermanentDeleteHistory(t, ss) })
+	t.Run("PermanentDeleteByChannelBatch", func(t *testing.T) { testPostStorePermanentDeleteByChannelBatch(t, ss) })
 }
 
 func testPostStoreSave(t *testing.T, ss store.Store) {
@@ -933,6 +941,21 @@ func testPostStoreSearch(t *testing.T, ss store.Store) {
 	if len(r13.Order) != 2 {
 		t.Fatal("returned wrong search result")
 	}
+
+	r14 := (<-ss.Post().Search(teamId, userId, &model.SearchParams{Terms: "Jersey corey", OrTerms: true, Limit: 1})).Data.(*model.PostList)
+	if len(r14.Order) != 1 {
+		t.Fatal("should've returned only one match")
+	}
+
+	r15 := (<-ss.Post().Search(teamId, userId, &model.SearchParams{Terms: "Jersey corey", OrTerms: true, MaxCreateAt: o1.CreateAt})).Data.(*model.PostList)
+	if _, ok := r15.Posts[o1.Id]; !ok {
+		t.Fatal("should've returned the post made at MaxCreateAt")
+	}
+	for _, post := range r15.Posts {
+		if post.CreateAt > o1.CreateAt {
+			t.Fatal("shouldn't have returned posts made after MaxCreateAt")
+		}
+	}
 }
 
 func testUserCountsWithPostsByDay(t *testing.T, ss store.Store) {
@@ -1731,3 +1754,231 @@ func testGetMaxPostSize(t *testing.T, ss store.Store) {
 	assert.Equal(t, model.POST_MESSAGE_MAX_RUNES_V2, (<-ss.Post().GetMaxPostSize()).Data.(int))
 	assert.Equal(t, model.POST_MESSAGE_MAX_RUNES_V2, (<-ss.Post().GetMaxPostSize()).Data.(int))
 }
+
+func testPostStoreGetPostsByUser(t *testing.T, ss store.Store) {
+	userId := model.NewId()
+
+	o1 := store.Must(ss.Post().Save(&model.Post{ChannelId: model.NewId(), UserId: userId, Message: "zz" + model.NewId() + "b", CreateAt: 1000})).(*model.Post)
+	o2 := store.Must(ss.Post().Save(&model.Post{ChannelId: model.NewId(), UserId: userId, Message: "zz" + model.NewId() + "b", CreateAt: 2000})).(*model.Post)
+	o3 := store.Must(ss.Post().Save(&model.Post{ChannelId: o1.ChannelId, UserId: userId, Message: "zz" + model.NewId() + "b", CreateAt: 3000})).(*model.Post)
+	store.Must(ss.Post().Delete(o3.Id, model.GetMillis()))
+	store.Must(ss.Post().Save(&model.Post{ChannelId: o1.ChannelId, UserId: model.NewId(), Message: "zz" + model.NewId() + "b"}))
+
+	posts := store.Must(ss.Post().GetPostsByUser(userId, 0, 10)).([]*model.Post)
+	if assert.Len(t, posts, 3) {
+		assert.Equal(t, o1.Id, posts[0].Id)
+		assert.Equal(t, o2.Id, posts[1].Id)
+		assert.Equal(t, o3.Id, posts[2].Id)
+		assert.NotEqual(t, int64(0), posts[2].DeleteAt, "deleted posts should be included")
+	}
+
+	posts = store.Must(ss.Post().GetPostsByUser(userId, 1, 1)).([]*model.Post)
+	if assert.Len(t, posts, 1) {
+		assert.Equal(t, o2.Id, posts[0].Id)
+	}
+}
+
+func testPostStoreGetPostsWithMessageContaining(t *testing.T, ss store.Store) {
+	term := "@zz" + model.NewId()
+
+	o1 := store.Must(ss.Post().Save(&model.Post{ChannelId: model.NewId(), UserId: model.NewId(), Message: "hello " + term})).(*model.Post)
+	o2 := store.Must(ss.Post().Save(&model.Post{ChannelId: model.NewId(), UserId: model.NewId(), Message: term + ", how are you?"})).(*model.Post)
+	store.Must(ss.Post().Save(&model.Post{ChannelId: model.NewId(), UserId: model.NewId(), Message: "nothing to see"}))
+
+	ids := func(posts []*model.Post) []string {
+		result := []string{}
+		for _, post := range posts {
+			result = append(result, post.Id)
+		}
+		return result
+	}
+
+	expected := []string{o1.Id, o2.Id}
+	if o2.Id < o1.Id {
+		expected = []string{o2.Id, o1.Id}
+	}
+
+	posts := store.Must(ss.Post().GetPostsWithMessageContaining(term, "", 10)).([]*model.Post)
+	assert.Equal(t, expected, ids(posts))
+
+	posts = store.Must(ss.Post().GetPostsWithMessageContaining(term, "", 1)).([]*model.Post)
+	assert.Equal(t, expected[:1], ids(posts))
+
+	posts = store.Must(ss.Post().GetPostsWithMessageContaining(term, expected[0], 10)).([]*model.Post)
+	assert.Equal(t, expected[1:], ids(posts))
+}
+
+func testPostStoreGetPostsWithPropsLargerThan(t *testing.T, ss store.Store) {
+	channelId := model.NewId()
+
+	large := store.Must(ss.Post().Save(&model.Post{ChannelId: channelId, UserId: model.NewId(), Message: "large", Props: model.StringInterface{"a": strings.Repeat("a", 6000)}})).(*model.Post)
+	larger := store.Must(ss.Post().Save(&model.Post{ChannelId: channelId, UserId: model.NewId(), Message: "larger", Props: model.StringInterface{"a": strings.Repeat("ü", 3500)}})).(*model.Post)
+	store.Must(ss.Post().Save(&model.Post{ChannelId: channelId, UserId: model.NewId(), Message: "small", Props: model.StringInterface{"a": "a"}}))
+
+	inChannel := func(sizes []*model.PostPropsSize) []*model.PostPropsSize {
+		result := []*model.PostPropsSize{}
+		for _, size := range sizes {
+			if size.ChannelId == channelId {
+				result = append(result, size)
+			}
+		}
+		return result
+	}
+
+	largeSize := &model.PostPropsSize{PostId: large.Id, ChannelId: channelId, UserId: large.UserId, CreateAt: large.CreateAt, Size: large.PropsSize()}
+	largerSize := &model.PostPropsSize{PostId: larger.Id, ChannelId: channelId, UserId: larger.UserId, CreateAt: larger.CreateAt, Size: larger.PropsSize()}
+
+	expected := []*model.PostPropsSize{largeSize, largerSize}
+	if larger.Id < large.Id {
+		expected = []*model.PostPropsSize{largerSize, largeSize}
+	}
+
+	sizes := store.Must(ss.Post().GetPostsWithPropsLargerThan(5000, "", 1000)).([]*model.PostPropsSize)
+	assert.Equal(t, expected, inChannel(sizes))
+
+	// The multibyte characters make the larger post's props take up more bytes, even though they're fewer runes
+	sizes = store.Must(ss.Post().GetPostsWithPropsLargerThan(large.PropsSize(), "", 1000)).([]*model.PostPropsSize)
+	assert.Equal(t, []*model.PostPropsSize{largerSize}, inChannel(sizes))
+
+	sizes = store.Must(ss.Post().GetPostsWithPropsLargerThan(5000, expected[0].PostId, 1000)).([]*model.PostPropsSize)
+	assert.Equal(t, expected[1:], inChannel(sizes))
+}
+
+func testPostStoreGetPostsForChannelExport(t *testing.T, ss store.Store) {
+	channelId := model.NewId()
+	userId := model.NewId()
+
+	first := store.Must(ss.Post().Save(&model.Post{ChannelId: channelId, UserId: userId, Message: "first", CreateAt: 1000})).(*model.Post)
+	second := store.Must(ss.Post().Save(&model.Post{ChannelId: channelId, UserId: userId, Message: "second", CreateAt: 2000})).(*model.Post)
+	third := store.Must(ss.Post().Save(&model.Post{ChannelId: channelId, UserId: userId, Message: "third", CreateAt: 2000})).(*model.Post)
+	deleted := store.Must(ss.Post().Save(&model.Post{ChannelId: channelId, UserId: userId, Message: "deleted", CreateAt: 3000})).(*model.Post)
+	store.Must(ss.Post().Delete(deleted.Id, model.GetMillis()))
+	store.Must(ss.Post().Save(&model.Post{ChannelId: model.NewId(), UserId: userId, Message: "other channel", CreateAt: 1500}))
+
+	// Posts made at the same time are ordered by their ids
+	if third.Id < second.Id {
+		second, third = third, second
+	}
+
+	ids := func(posts []*model.Post) []string {
+		result := []string{}
+		for _, post := range posts {
+			result = append(result, post.Id)
+		}
+		return result
+	}
+
+	posts := store.Must(ss.Post().GetPostsForChannelExport(channelId, 0, "", 10)).([]*model.Post)
+	assert.Equal(t, []string{first.Id, second.Id, third.Id}, ids(posts))
+
+	posts = store.Must(ss.Post().GetPostsForChannelExport(channelId, 0, "", 2)).([]*model.Post)
+	assert.Equal(t, []string{first.Id, second.Id}, ids(posts))
+
+	posts = store.Must(ss.Post().GetPostsForChannelExport(channelId, second.CreateAt, second.Id, 2)).([]*model.Post)
+	assert.Equal(t, []string{third.Id}, ids(posts))
+
+	posts = store.Must(ss.Post().GetPostsForChannelExport(channelId, third.CreateAt, third.Id, 2)).([]*model.Post)
+	assert.Empty(t, posts)
+}
+
+func testPostStoreHistory(t *testing.T, ss store.Store) {
+	post := store.Must(ss.Post().Save(&model.Post{ChannelId: model.NewId(), UserId: model.NewId(), Message: "first"})).(*model.Post)
+	editorId := model.NewId()
+
+	store.Must(ss.Post().SaveHistory(model.NewPostHistory(post, editorId, post.CreateAt+2)))
+
+	post.Message = "second"
+	store.Must(ss.Post().SaveHistory(model.NewPostHistory(post, post.UserId, post.CreateAt+1)))
+
+	result := <-ss.Post().SaveHistory(&model.PostHistory{PostId: post.Id, ChannelId: post.ChannelId, EditAt: 1})
+	assert.NotNil(t, result.Err, "should require an editor")
+
+	history := store.Must(ss.Post().GetHistory(post.Id)).([]*model.PostHistory)
+	require.Len(t, history, 2)
+	assert.Equal(t, "second", history[0].Message, "should be ordered by when the edits were made")
+	assert.Equal(t, post.UserId, history[0].EditorId)
+	assert.Equal(t, "first", history[1].Message)
+	assert.Equal(t, editorId, history[1].EditorId)
+
+	history = store.Must(ss.Post().GetHistory(model.NewId())).([]*model.PostHistory)
+	assert.Len(t, history, 0)
+}
+
+func testPostStorePermanentDeleteHistory(t *testing.T, ss store.Store) {
+	savePostWithHistory := func(post *model.Post) *model.Post {
+		post.Message = "zz" + model.NewId()
+		post = store.Must(ss.Post().Save(post)).(*model.Post)
+		store.Must(ss.Post().SaveHistory(model.NewPostHistory(post, post.UserId, post.CreateAt+1)))
+		return post
+	}
+
+	historyCount := func(post *model.Post) int {
+		return len(store.Must(ss.Post().GetHistory(post.Id)).([]*model.PostHistory))
+	}
+
+	t.Run("by user", func(t *testing.T) {
+		userId := model.NewId()
+		root := savePostWithHistory(&model.Post{ChannelId: model.NewId(), UserId: userId})
+		reply := savePostWithHistory(&model.Post{ChannelId: root.ChannelId, UserId: model.NewId(), RootId: root.Id})
+		comment := savePostWithHistory(&model.Post{ChannelId: model.NewId(), UserId: userId, RootId: model.NewId()})
+
+		store.Must(ss.Post().PermanentDeleteByUser(userId))
+
+		assert.Equal(t, 0, historyCount(root))
+		assert.Equal(t, 0, historyCount(reply), "should delete the history of replies to the user's posts")
+		assert.Equal(t, 0, historyCount(comment))
+	})
+
+	t.Run("by channel", func(t *testing.T) {
+		post := savePostWithHistory(&model.Post{ChannelId: model.NewId(), UserId: model.NewId()})
+		other := savePostWithHistory(&model.Post{ChannelId: model.NewId(), UserId: model.NewId()})
+
+		store.Must(ss.Post().PermanentDeleteByChannel(post.ChannelId))
+
+		assert.Equal(t, 0, historyCount(post))
+		assert.Equal(t, 1, historyCount(other))
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		old := savePostWithHistory(&model.Post{ChannelId: model.NewId(), UserId: model.NewId(), CreateAt: 1000})
+		recent := savePostWithHistory(&model.Post{ChannelId: model.NewId(), UserId: model.NewId(), CreateAt: 100000})
+
+		store.Must(ss.Post().PermanentDeleteBatch(2000, 1000))
+
+		assert.Equal(t, 0, historyCount(old))
+		assert.Equal(t, 1, historyCount(recent))
+	})
+}
+
+func testPostStorePermanentDeleteByChannelBatch(t *testing.T, ss store.Store) {
+	channelId := model.NewId()
+	var posts []*model.Post
+	for i := 0; i < 3; i++ {
+		post := store.Must(ss.Post().Save(&model.Post{ChannelId: channelId, UserId: model.NewId(), Message: "zz" + model.NewId()})).(*model.Post)
+		posts = append(posts, post)
+	}
+	store.Must(ss.Post().SaveHistory(model.NewPostHistory(posts[0], posts[0].UserId, posts[0].CreateAt+1)))
+	other := store.Must(ss.Post().Save(&model.Post{ChannelId: model.NewId(), UserId: model.NewId(), Message: "zz" + model.NewId()})).(*model.Post)
+
+	// Each batch deletes up to the limit of both the posts and their earlier versions
+	result := <-ss.Post().PermanentDeleteByChannelBatch(channelId, 2)
+	require.Nil(t, result.Err)
+	assert.Equal(t, int64(3), result.Data.(int64))
+
+	result = <-ss.Post().PermanentDeleteByChannelBatch(channelId, 2)
+	require.Nil(t, result.Err)
+	assert.Equal(t, int64(1), result.Data.(int64))
+
+	result = <-ss.Post().PermanentDeleteByChannelBatch(channelId, 2)
+	require.Nil(t, result.Err)
+	assert.Equal(t, int64(0), result.Data.(int64))
+
+	for _, post := range posts {
+		result = <-ss.Post().GetSingle(post.Id)
+		assert.NotNil(t, result.Err)
+	}
+	assert.Empty(t, store.Must(ss.Post().GetHistory(posts[0].Id)))
+
+	result = <-ss.Post().GetSingle(other.Id)
+	assert.Nil(t, result.Err, "posts in other channels should be left alone")
+}
@@ -4,9 +4,11 @@
 package storetest
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/store"
@@ -14,15 +16,19 @@ import (
 
 func TestPreferenceStore(t *testing.T, ss store.Store) {
 	t.Run("PreferenceSave", func(t *testing.T) { testPreferenceSave(t, ss) })
+	t.Run("PreferenceSaveBatch", func(t *testing.T) { testPreferenceSaveBatch(t, ss) })
 	t.Run("PreferenceGet", func(t *testing.T) { testPreferenceGet(t, ss) })
 	t.Run("PreferenceGetCategory", func(t *testing.T) { testPreferenceGetCategory(t, ss) })
+	t.Run("PreferenceGetUserIdsByCategoryAndName", func(t *testing.T) { testPreferenceGetUserIdsByCategoryAndName(t, ss) })
 	t.Run("PreferenceGetAll", func(t *testing.T) { testPreferenceGetAll(t, ss) })
+	t.Run("PreferenceGetPage", func(t *testing.T) { testPreferenceGetPage(t, ss) })
 	t.Run("PreferenceDeleteByUser", func(t *testing.T) { testPreferenceDeleteByUser(t, ss) })
 	t.Run("IsFeatureEnabled", func(t *testing.T) { testIsFeatureEnabled(t, ss) })
 	t.Run("PreferenceDelete", func(t *testing.T) { testPreferenceDelete(t, ss) })
 	t.Run("PreferenceDeleteCategory", func(t *testing.T) { testPreferenceDeleteCategory(t, ss) })
 	t.Run("PreferenceDeleteCategoryAndName", func(t *testing.T) { testPreferenceDeleteCategoryAndName(t, ss) })
 	t.Run("PreferenceCleanupFlagsBatch", func(t *testing.T) { testPreferenceCleanupFlagsBatch(t, ss) })
+	t.Run("PreferenceCleanupChannelsBatch", func(t *testing.T) { testPreferenceCleanupChannelsBatch(t, ss) })
 }
 
 func testPreferenceSave(t *testing.T, ss store.Store) {
@@ -65,6 +71,60 @@ func testPreferenceSave(t *testing.T, ss store.Store) {
 	}
 }
 
+func testPreferenceSaveBatch(t *testing.T, ss store.Store) {
+	userId := model.NewId()
+
+	var preferences model.Preferences
+	for i := 0; i < model.PREFERENCES_MAX_UPDATE+10; i++ {
+		preferences = append(preferences, model.Preference{
+			UserId:   userId,
+			Category: model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL,
+			Name:     model.NewId(),
+			Value:    "true",
+		})
+	}
+	// a later preference with the same key replaces an earlier one
+	duplicate := preferences[0]
+	duplicate.Value = "false"
+	preferences = append(preferences, duplicate)
+
+	result := <-ss.Preference().Save(&preferences)
+	require.Nil(t, result.Err)
+
+	saved := store.Must(ss.Preference().GetAll(userId)).(model.Preferences)
+	assert.Len(t, saved, model.PREFERENCES_MAX_UPDATE+10)
+
+	preference := store.Must(ss.Preference().Get(userId, duplicate.Category, duplicate.Name)).(model.Preference)
+	assert.Equal(t, "false", preference.Value)
+
+	t.Run("nothing is saved if any preference is invalid", func(t *testing.T) {
+		userId := model.NewId()
+		preferences := model.Preferences{
+			{UserId: userId, Category: model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, Name: model.NewId(), Value: "true"},
+			{UserId: userId, Category: model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, Name: model.NewId(), Value: strings.Repeat("a", 2001)},
+		}
+
+		result := <-ss.Preference().Save(&preferences)
+		assert.NotNil(t, result.Err)
+
+		saved := store.Must(ss.Preference().GetAll(userId)).(model.Preferences)
+		assert.Empty(t, saved)
+	})
+
+	t.Run("nothing is updated if any preference fails to save", func(t *testing.T) {
+		preferences := model.Preferences{
+			{UserId: userId, Category: duplicate.Category, Name: duplicate.Name, Value: "true"},
+			{UserId: userId, Category: model.PREFERENCE_CATEGORY_THEME, Name: model.NewId(), Value: "not a theme"},
+		}
+
+		result := <-ss.Preference().Save(&preferences)
+		assert.NotNil(t, result.Err)
+
+		preference := store.Must(ss.Preference().Get(userId, duplicate.Category, duplicate.Name)).(model.Preference)
+		assert.Equal(t, "false", preference.Value)
+	})
+}
+
 func testPreferenceGet(t *testing.T, ss store.Store) {
 	userId := model.NewId()
 	category := model.PREFERENCE_CATEGORY_DIRECT_CHANNEL_SHOW
@@ -409,6 +469,30 @@ func testPreferenceDeleteCategoryAndName(t *testing.T, ss store.Store) {
 	}
 }
 
+func testPreferenceGetPage(t *testing.T, ss store.Store) {
+	userId := model.NewId()
+
+	preferences := model.Preferences{
+		{UserId: userId, Category: model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, Name: "b" + model.NewId()[1:]},
+		{UserId: userId, Category: model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, Name: "a" + model.NewId()[1:]},
+		{UserId: userId, Category: model.PREFERENCE_CATEGORY_DISPLAY_SETTINGS, Name: model.PREFERENCE_NAME_COLLAPSE_SETTING},
+		{UserId: model.NewId(), Category: model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, Name: model.NewId()},
+	}
+	store.Must(ss.Preference().Save(&preferences))
+
+	page := store.Must(ss.Preference().GetPage(userId, "", 0, 10)).(model.Preferences)
+	assert.Equal(t, model.Preferences{preferences[2], preferences[1], preferences[0]}, page)
+
+	page = store.Must(ss.Preference().GetPage(userId, "", 1, 1)).(model.Preferences)
+	assert.Equal(t, model.Preferences{preferences[1]}, page)
+
+	page = store.Must(ss.Preference().GetPage(userId, model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, 0, 10)).(model.Preferences)
+	assert.Equal(t, model.Preferences{preferences[1], preferences[0]}, page)
+
+	page = store.Must(ss.Preference().GetPage(userId, model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, 2, 10)).(model.Preferences)
+	assert.Empty(t, page)
+}
+
 func testPreferenceCleanupFlagsBatch(t *testing.T, ss store.Store) {
 	category := model.PREFERENCE_CATEGORY_FLAGGED_POST
 	userId := model.NewId()
@@ -445,3 +529,65 @@ func testPreferenceCleanupFlagsBatch(t *testing.T, ss store.Store) {
 	result = <-ss.Preference().Get(userId, category, preference2.Name)
 	assert.NotNil(t, result.Err)
 }
+
+func testPreferenceCleanupChannelsBatch(t *testing.T, ss store.Store) {
+	userId := model.NewId()
+
+	channel := &model.Channel{
+		TeamId:      model.NewId(),
+		DisplayName: "Channel",
+		Name:        "zz" + model.NewId() + "b",
+		Type:        model.CHANNEL_OPEN,
+	}
+	channel = store.Must(ss.Channel().Save(channel, -1)).(*model.Channel)
+	defer func() { <-ss.Channel().PermanentDelete(channel.Id) }()
+
+	existing := model.Preference{UserId: userId, Category: model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, Name: channel.Id, Value: "true"}
+	orphaned := model.Preference{UserId: userId, Category: model.PREFERENCE_CATEGORY_FAVORITE_CHANNEL, Name: model.NewId(), Value: "true"}
+	orphanedOpenTime := model.Preference{UserId: userId, Category: model.PREFERENCE_CATEGORY_CHANNEL_OPEN_TIME, Name: orphaned.Name, Value: "1000"}
+	otherCategory := model.Preference{UserId: userId, Category: model.PREFERENCE_CATEGORY_DIRECT_CHANNEL_SHOW, Name: model.NewId(), Value: "true"}
+
+	store.Must(ss.Preference().Save(&model.Preferences{existing, orphaned, orphanedOpenTime, otherCategory}))
+
+	result := <-ss.Preference().CleanupChannelsBatch(10000)
+	require.Nil(t, result.Err)
+	assert.True(t, result.Data.(int64) >= 2)
+
+	result = <-ss.Preference().Get(userId, existing.Category, existing.Name)
+	assert.Nil(t, result.Err)
+
+	result = <-ss.Preference().Get(userId, orphaned.Category, orphaned.Name)
+	assert.NotNil(t, result.Err)
+
+	result = <-ss.Preference().Get(userId, orphanedOpenTime.Category, orphanedOpenTime.Name)
+	assert.NotNil(t, result.Err)
+
+	result = <-ss.Preference().Get(userId, otherCategory.Category, otherCategory.Name)
+	assert.Nil(t, result.Err, "preferences in other categories shouldn't be removed")
+}
+
+func testPreferenceGetUserIdsByCategoryAndName(t *testing.T, ss store.Store) {
+	category := model.NewId()
+	name := model.NewId()
+	userId1 := model.NewId()
+	userId2 := model.NewId()
+
+	preferences := model.Preferences{
+		{UserId: userId1, Category: category, Name: name, Value: "true"},
+		{UserId: userId2, Category: category, Name: name, Value: "true"},
+		// same category, different name
+		{UserId: model.NewId(), Category: category, Name: model.NewId(), Value: "true"},
+		// same name, different category
+		{UserId: model.NewId(), Category: model.NewId(), Name: name, Value: "true"},
+	}
+
+	store.Must(ss.Preference().Save(&preferences))
+
+	result := <-ss.Preference().GetUserIdsByCategoryAndName(category, name)
+	require.Nil(t, result.Err)
+	assert.ElementsMatch(t, []string{userId1, userId2}, result.Data.([]string))
+
+	result = <-ss.Preference().GetUserIdsByCategoryAndName(category, model.NewId())
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]string), 0)
+}
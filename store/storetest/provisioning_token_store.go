@@ -0,0 +1,73 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package storetest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func TestProvisioningTokenStore(t *testing.T, ss store.Store) {
+	t.Run("SaveAndGet", func(t *testing.T) { testProvisioningTokenStoreSaveAndGet(t, ss) })
+	t.Run("GetAll", func(t *testing.T) { testProvisioningTokenStoreGetAll(t, ss) })
+	t.Run("Delete", func(t *testing.T) { testProvisioningTokenStoreDelete(t, ss) })
+}
+
+func testProvisioningTokenStoreSaveAndGet(t *testing.T, ss store.Store) {
+	token := store.Must(ss.ProvisioningToken().Save(&model.ProvisioningToken{CreatorId: model.NewId(), Description: "okta"})).(*model.ProvisioningToken)
+	assert.Len(t, token.Id, 26)
+	assert.Len(t, token.Token, 26)
+
+	result := <-ss.ProvisioningToken().Get(token.Id)
+	require.Nil(t, result.Err)
+	assert.Equal(t, token, result.Data.(*model.ProvisioningToken))
+
+	result = <-ss.ProvisioningToken().GetByToken(token.Token)
+	require.Nil(t, result.Err)
+	assert.Equal(t, token, result.Data.(*model.ProvisioningToken))
+
+	result = <-ss.ProvisioningToken().Get(model.NewId())
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+
+	result = <-ss.ProvisioningToken().GetByToken(model.NewId())
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+
+	result = <-ss.ProvisioningToken().Save(&model.ProvisioningToken{})
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "model.provisioning_token.is_valid.creator_id.app_error", result.Err.Id)
+}
+
+func testProvisioningTokenStoreGetAll(t *testing.T, ss store.Store) {
+	token1 := store.Must(ss.ProvisioningToken().Save(&model.ProvisioningToken{CreatorId: model.NewId()})).(*model.ProvisioningToken)
+	token2 := store.Must(ss.ProvisioningToken().Save(&model.ProvisioningToken{CreatorId: model.NewId()})).(*model.ProvisioningToken)
+
+	tokens := store.Must(ss.ProvisioningToken().GetAll(0, 1000)).([]*model.ProvisioningToken)
+	ids := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		ids = append(ids, token.Id)
+	}
+	assert.Contains(t, ids, token1.Id)
+	assert.Contains(t, ids, token2.Id)
+
+	tokens = store.Must(ss.ProvisioningToken().GetAll(0, 1)).([]*model.ProvisioningToken)
+	assert.Len(t, tokens, 1)
+}
+
+func testProvisioningTokenStoreDelete(t *testing.T, ss store.Store) {
+	token := store.Must(ss.ProvisioningToken().Save(&model.ProvisioningToken{CreatorId: model.NewId()})).(*model.ProvisioningToken)
+
+	require.Nil(t, (<-ss.ProvisioningToken().Delete(token.Id)).Err)
+
+	result := <-ss.ProvisioningToken().GetByToken(token.Token)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+}
@@ -6,6 +6,9 @@ package storetest
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/store"
 )
@@ -14,6 +17,8 @@ func TestReactionStore(t *testing.T, ss store.Store) {
 	t.Run("ReactionSave", func(t *testing.T) { testReactionSave(t, ss) })
 	t.Run("ReactionDelete", func(t *testing.T) { testReactionDelete(t, ss) })
 	t.Run("ReactionGetForPost", func(t *testing.T) { testReactionGetForPost(t, ss) })
+	t.Run("ReactionGetForUser", func(t *testing.T) { testReactionGetForUser(t, ss) })
+	t.Run("ReactionGetCountsForPosts", func(t *testing.T) { testReactionGetCountsForPosts(t, ss) })
 	t.Run("ReactionDeleteAllWithEmojiName", func(t *testing.T) { testReactionDeleteAllWithEmojiName(t, ss) })
 	t.Run("PermanentDeleteBatch", func(t *testing.T) { testReactionStorePermanentDeleteBatch(t, ss) })
 }
@@ -209,6 +214,61 @@ func testReactionGetForPost(t *testing.T, ss store.Store) {
 	}
 }
 
+func testReactionGetCountsForPosts(t *testing.T, ss store.Store) {
+	postId1 := model.NewId()
+	postId2 := model.NewId()
+	otherPostId := model.NewId()
+
+	userId := model.NewId()
+
+	reactions := []*model.Reaction{
+		{UserId: userId, PostId: postId1, EmojiName: "smile"},
+		{UserId: model.NewId(), PostId: postId1, EmojiName: "smile"},
+		{UserId: model.NewId(), PostId: postId1, EmojiName: "smile"},
+		{UserId: model.NewId(), PostId: postId1, EmojiName: "sad"},
+		{UserId: userId, PostId: postId2, EmojiName: "angry"},
+		{UserId: userId, PostId: otherPostId, EmojiName: "smile"},
+	}
+
+	for _, reaction := range reactions {
+		store.Must(ss.Reaction().Save(reaction))
+	}
+
+	counts := store.Must(ss.Reaction().GetCountsForPosts([]string{postId1, postId2}, userId)).([]*model.ReactionCount)
+	require.Len(t, counts, 3)
+
+	byPost := make(map[string]map[string]*model.ReactionCount)
+	for _, count := range counts {
+		if byPost[count.PostId] == nil {
+			byPost[count.PostId] = make(map[string]*model.ReactionCount)
+		}
+		byPost[count.PostId][count.EmojiName] = count
+	}
+
+	require.NotNil(t, byPost[postId1]["smile"])
+	assert.Equal(t, int64(3), byPost[postId1]["smile"].Count)
+	assert.True(t, byPost[postId1]["smile"].Me)
+
+	require.NotNil(t, byPost[postId1]["sad"])
+	assert.Equal(t, int64(1), byPost[postId1]["sad"].Count)
+	assert.False(t, byPost[postId1]["sad"].Me)
+
+	require.NotNil(t, byPost[postId2]["angry"])
+	assert.Equal(t, int64(1), byPost[postId2]["angry"].Count)
+	assert.True(t, byPost[postId2]["angry"].Me)
+
+	assert.Nil(t, byPost[otherPostId])
+
+	counts = store.Must(ss.Reaction().GetCountsForPosts([]string{postId1}, model.NewId())).([]*model.ReactionCount)
+	require.Len(t, counts, 2)
+	for _, count := range counts {
+		assert.False(t, count.Me)
+	}
+
+	counts = store.Must(ss.Reaction().GetCountsForPosts([]string{}, userId)).([]*model.ReactionCount)
+	assert.Len(t, counts, 0)
+}
+
 func testReactionDeleteAllWithEmojiName(t *testing.T, ss store.Store) {
 	emojiToDelete := model.NewId()
 
@@ -348,3 +408,35 @@ func testReactionStorePermanentDeleteBatch(t *testing.T, ss store.Store) {
 		t.Fatalf("expected 1 reaction. Got: %v", len(returned))
 	}
 }
+
+func testReactionGetForUser(t *testing.T, ss store.Store) {
+	userId := model.NewId()
+	postId := model.NewId()
+
+	reactions := []*model.Reaction{
+		{UserId: userId, PostId: postId, EmojiName: "smile"},
+		{UserId: userId, PostId: model.NewId(), EmojiName: "smile"},
+		{UserId: model.NewId(), PostId: postId, EmojiName: "smile"},
+	}
+	for _, reaction := range reactions {
+		store.Must(ss.Reaction().Save(reaction))
+	}
+
+	if result := <-ss.Reaction().GetForUser(userId); result.Err != nil {
+		t.Fatal(result.Err)
+	} else if returned := result.Data.([]*model.Reaction); len(returned) != 2 {
+		t.Fatal("should've returned the 2 reactions of the user")
+	} else {
+		for _, reaction := range returned {
+			if reaction.UserId != userId {
+				t.Fatal("should only have returned reactions of the user")
+			}
+		}
+	}
+
+	if result := <-ss.Reaction().GetForUser(model.NewId()); result.Err != nil {
+		t.Fatal(result.Err)
+	} else if returned := result.Data.([]*model.Reaction); len(returned) != 0 {
+		t.Fatal("shouldn't have returned any reactions")
+	}
+}
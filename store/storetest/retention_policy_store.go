@@ -0,0 +1,252 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package storetest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func TestRetentionPolicyStore(t *testing.T, ss store.Store) {
+	t.Run("SaveAndGet", func(t *testing.T) { testRetentionPolicyStoreSaveAndGet(t, ss) })
+	t.Run("Assignments", func(t *testing.T) { testRetentionPolicyStoreAssignments(t, ss) })
+	t.Run("Delete", func(t *testing.T) { testRetentionPolicyStoreDelete(t, ss) })
+	t.Run("DeletePosts", func(t *testing.T) { testRetentionPolicyStoreDeletePosts(t, ss) })
+	t.Run("DeleteFiles", func(t *testing.T) { testRetentionPolicyStoreDeleteFiles(t, ss) })
+}
+
+func makeRetentionPolicyForTest(ss store.Store, messageDays int) *model.RetentionPolicy {
+	return store.Must(ss.RetentionPolicy().Save(&model.RetentionPolicy{
+		DisplayName:          "policy " + model.NewId(),
+		MessageRetentionDays: messageDays,
+	})).(*model.RetentionPolicy)
+}
+
+func makeRetentionChannelForTest(ss store.Store, teamId string) *model.Channel {
+	return store.Must(ss.Channel().Save(&model.Channel{
+		TeamId:      teamId,
+		DisplayName: "Retention",
+		Name:        "zz" + model.NewId() + "b",
+		Type:        model.CHANNEL_OPEN,
+	}, -1)).(*model.Channel)
+}
+
+func makeRetentionPostForTest(ss store.Store, channelId string, createAt int64, pinned bool) *model.Post {
+	return store.Must(ss.Post().Save(&model.Post{
+		ChannelId: channelId,
+		UserId:    model.NewId(),
+		Message:   "message " + model.NewId(),
+		CreateAt:  createAt,
+		IsPinned:  pinned,
+	})).(*model.Post)
+}
+
+func postExistsForTest(ss store.Store, postId string) bool {
+	return (<-ss.Post().GetSingle(postId)).Err == nil
+}
+
+func testRetentionPolicyStoreSaveAndGet(t *testing.T, ss store.Store) {
+	policy := makeRetentionPolicyForTest(ss, 30)
+	assert.Len(t, policy.Id, 26)
+
+	result := <-ss.RetentionPolicy().Save(policy)
+	assert.NotNil(t, result.Err)
+
+	result = <-ss.RetentionPolicy().Get(policy.Id)
+	require.Nil(t, result.Err)
+	fetched := result.Data.(*model.RetentionPolicy)
+	assert.Equal(t, policy.DisplayName, fetched.DisplayName)
+	assert.Equal(t, 30, fetched.MessageRetentionDays)
+	assert.Equal(t, []string{}, fetched.ChannelIds)
+
+	fetched.FileRetentionDays = 60
+	require.Nil(t, (<-ss.RetentionPolicy().Update(fetched)).Err)
+
+	result = <-ss.RetentionPolicy().Get(policy.Id)
+	require.Nil(t, result.Err)
+	assert.Equal(t, 60, result.Data.(*model.RetentionPolicy).FileRetentionDays)
+
+	result = <-ss.RetentionPolicy().Get(model.NewId())
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "store.sql_retention_policy.get.missing.app_error", result.Err.Id)
+}
+
+func testRetentionPolicyStoreAssignments(t *testing.T, ss store.Store) {
+	policy := makeRetentionPolicyForTest(ss, 30)
+	other := makeRetentionPolicyForTest(ss, 60)
+
+	channelId := model.NewId()
+	teamId := model.NewId()
+
+	require.Nil(t, (<-ss.RetentionPolicy().AddChannels(policy.Id, []string{channelId})).Err)
+	require.Nil(t, (<-ss.RetentionPolicy().AddTeams(policy.Id, []string{teamId})).Err)
+
+	// A channel can only be governed by one policy
+	result := <-ss.RetentionPolicy().AddChannels(other.Id, []string{model.NewId(), channelId})
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "store.sql_retention_policy.add_assignments.exists.app_error", result.Err.Id)
+
+	result = <-ss.RetentionPolicy().Get(other.Id)
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.(*model.RetentionPolicy).ChannelIds, 0)
+
+	result = <-ss.RetentionPolicy().GetAll()
+	require.Nil(t, result.Err)
+	found := false
+	for _, p := range result.Data.([]*model.RetentionPolicy) {
+		if p.Id == policy.Id {
+			found = true
+			assert.Equal(t, []string{channelId}, p.ChannelIds)
+			assert.Equal(t, []string{teamId}, p.TeamIds)
+		}
+	}
+	assert.True(t, found)
+
+	require.Nil(t, (<-ss.RetentionPolicy().RemoveChannels(policy.Id, []string{channelId})).Err)
+	require.Nil(t, (<-ss.RetentionPolicy().RemoveTeams(policy.Id, []string{teamId})).Err)
+
+	result = <-ss.RetentionPolicy().Get(policy.Id)
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.(*model.RetentionPolicy).ChannelIds, 0)
+	assert.Len(t, result.Data.(*model.RetentionPolicy).TeamIds, 0)
+
+	require.Nil(t, (<-ss.RetentionPolicy().AddChannels(other.Id, []string{channelId})).Err)
+	require.Nil(t, (<-ss.RetentionPolicy().RemoveChannels(other.Id, []string{channelId})).Err)
+}
+
+func testRetentionPolicyStoreDelete(t *testing.T, ss store.Store) {
+	policy := makeRetentionPolicyForTest(ss, 30)
+	channelId := model.NewId()
+	require.Nil(t, (<-ss.RetentionPolicy().AddChannels(policy.Id, []string{channelId})).Err)
+
+	require.Nil(t, (<-ss.RetentionPolicy().Delete(policy.Id)).Err)
+	assert.NotNil(t, (<-ss.RetentionPolicy().Get(policy.Id)).Err)
+
+	// The channel's assignment is removed along with the policy
+	other := makeRetentionPolicyForTest(ss, 30)
+	assert.Nil(t, (<-ss.RetentionPolicy().AddChannels(other.Id, []string{channelId})).Err)
+	require.Nil(t, (<-ss.RetentionPolicy().Delete(other.Id)).Err)
+}
+
+func testRetentionPolicyStoreDeletePosts(t *testing.T, ss store.Store) {
+	cutoff := model.GetMillis() - 10*24*60*60*1000
+	teamId := model.NewId()
+
+	policy := makeRetentionPolicyForTest(ss, 10)
+	teamPolicy := makeRetentionPolicyForTest(ss, 10)
+	defer func() {
+		store.Must(ss.RetentionPolicy().Delete(policy.Id))
+		store.Must(ss.RetentionPolicy().Delete(teamPolicy.Id))
+	}()
+
+	channel := makeRetentionChannelForTest(ss, teamId)
+	teamChannel := makeRetentionChannelForTest(ss, teamId)
+	store.Must(ss.RetentionPolicy().AddChannels(policy.Id, []string{channel.Id}))
+	store.Must(ss.RetentionPolicy().AddTeams(teamPolicy.Id, []string{teamId}))
+
+	old := makeRetentionPostForTest(ss, channel.Id, cutoff-1, false)
+	oldPinned := makeRetentionPostForTest(ss, channel.Id, cutoff-2, true)
+	atCutoff := makeRetentionPostForTest(ss, channel.Id, cutoff, false)
+	recent := makeRetentionPostForTest(ss, channel.Id, cutoff+1, false)
+	oldInTeam := makeRetentionPostForTest(ss, teamChannel.Id, cutoff-1, false)
+
+	store.Must(ss.Reaction().Save(&model.Reaction{UserId: model.NewId(), PostId: old.Id, EmojiName: "smile"}))
+	store.Must(ss.Thread().Save(&model.Thread{PostId: old.Id, ChannelId: channel.Id, Participants: model.StringArray{}}))
+	store.Must(ss.Thread().SaveMembership(&model.ThreadMembership{PostId: old.Id, UserId: model.NewId(), Following: true}))
+
+	result := <-ss.RetentionPolicy().CountPostsForDeletion(policy.Id, cutoff, true)
+	require.Nil(t, result.Err)
+	assert.Equal(t, int64(1), result.Data.(int64))
+
+	result = <-ss.RetentionPolicy().CountPostsForDeletion(policy.Id, cutoff, false)
+	require.Nil(t, result.Err)
+	assert.Equal(t, int64(2), result.Data.(int64))
+
+	result = <-ss.RetentionPolicy().PermanentDeletePostsBatch(policy.Id, cutoff, true, 1000)
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]*model.Post), 1)
+
+	assert.False(t, postExistsForTest(ss, old.Id))
+	assert.True(t, postExistsForTest(ss, oldPinned.Id))
+	assert.True(t, postExistsForTest(ss, atCutoff.Id))
+	assert.True(t, postExistsForTest(ss, recent.Id))
+
+	// The team's policy doesn't apply to the channel with its own policy, and vice versa
+	assert.True(t, postExistsForTest(ss, oldInTeam.Id))
+
+	reactions := store.Must(ss.Reaction().GetForPost(old.Id, false)).([]*model.Reaction)
+	assert.Len(t, reactions, 0)
+	assert.NotNil(t, (<-ss.Thread().Get(old.Id)).Err)
+	memberships := store.Must(ss.Thread().GetMembershipsForThread(old.Id)).([]*model.ThreadMembership)
+	assert.Len(t, memberships, 0)
+
+	result = <-ss.RetentionPolicy().PermanentDeletePostsBatch(teamPolicy.Id, cutoff, false, 1000)
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]*model.Post), 1)
+	assert.False(t, postExistsForTest(ss, oldInTeam.Id))
+	assert.True(t, postExistsForTest(ss, oldPinned.Id))
+
+	// Batches are limited in size
+	for i := 0; i < 3; i++ {
+		makeRetentionPostForTest(ss, channel.Id, cutoff-10, false)
+	}
+
+	result = <-ss.RetentionPolicy().PermanentDeletePostsBatch(policy.Id, cutoff, false, 2)
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]*model.Post), 2)
+
+	result = <-ss.RetentionPolicy().PermanentDeletePostsBatch(policy.Id, cutoff, false, 2)
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]*model.Post), 2)
+	assert.False(t, postExistsForTest(ss, oldPinned.Id))
+
+	result = <-ss.RetentionPolicy().PermanentDeletePostsBatch(policy.Id, cutoff, false, 2)
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]*model.Post), 0)
+	assert.True(t, postExistsForTest(ss, atCutoff.Id))
+}
+
+func testRetentionPolicyStoreDeleteFiles(t *testing.T, ss store.Store) {
+	cutoff := model.GetMillis() - 10*24*60*60*1000
+
+	policy := makeRetentionPolicyForTest(ss, 0)
+	defer store.Must(ss.RetentionPolicy().Delete(policy.Id))
+
+	channel := makeRetentionChannelForTest(ss, model.NewId())
+	store.Must(ss.RetentionPolicy().AddChannels(policy.Id, []string{channel.Id}))
+
+	post := makeRetentionPostForTest(ss, channel.Id, cutoff+1, false)
+	pinned := makeRetentionPostForTest(ss, channel.Id, cutoff+1, true)
+
+	oldFile := store.Must(ss.FileInfo().Save(&model.FileInfo{CreatorId: post.UserId, PostId: post.Id, Path: "file.txt", CreateAt: cutoff - 1})).(*model.FileInfo)
+	newFile := store.Must(ss.FileInfo().Save(&model.FileInfo{CreatorId: post.UserId, PostId: post.Id, Path: "file.txt", CreateAt: cutoff})).(*model.FileInfo)
+	pinnedFile := store.Must(ss.FileInfo().Save(&model.FileInfo{CreatorId: pinned.UserId, PostId: pinned.Id, Path: "file.txt", CreateAt: cutoff - 1})).(*model.FileInfo)
+
+	result := <-ss.RetentionPolicy().CountFilesForDeletion(policy.Id, cutoff, true)
+	require.Nil(t, result.Err)
+	assert.Equal(t, int64(1), result.Data.(int64))
+
+	result = <-ss.RetentionPolicy().CountFilesForDeletion(policy.Id, cutoff, false)
+	require.Nil(t, result.Err)
+	assert.Equal(t, int64(2), result.Data.(int64))
+
+	result = <-ss.RetentionPolicy().PermanentDeleteFilesBatch(policy.Id, cutoff, true, 1000)
+	require.Nil(t, result.Err)
+	assert.Equal(t, int64(1), result.Data.(int64))
+
+	assert.NotNil(t, (<-ss.FileInfo().Get(oldFile.Id)).Err)
+	assert.Nil(t, (<-ss.FileInfo().Get(newFile.Id)).Err)
+	assert.Nil(t, (<-ss.FileInfo().Get(pinnedFile.Id)).Err, "the files of pinned posts should be exempt")
+	assert.True(t, postExistsForTest(ss, post.Id))
+
+	result = <-ss.RetentionPolicy().PermanentDeleteFilesBatch(policy.Id, cutoff, false, 1000)
+	require.Nil(t, result.Err)
+	assert.Equal(t, int64(1), result.Data.(int64))
+	assert.NotNil(t, (<-ss.FileInfo().Get(pinnedFile.Id)).Err)
+}
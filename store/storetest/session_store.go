@@ -10,11 +10,13 @@ import (
 	"github.com/mattermost/mattermost-server/store"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSessionStore(t *testing.T, ss store.Store) {
 	// Run serially to prevent interfering with other tests
-	testSessionCleanup(t, ss)
+	testSessionPermanentDeleteExpiredBatch(t, ss)
+	testSessionPermanentDeleteOrphanedBatch(t, ss)
 
 	t.Run("Save", func(t *testing.T) { testSessionStoreSave(t, ss) })
 	t.Run("SessionGet", func(t *testing.T) { testSessionGet(t, ss) })
@@ -26,7 +28,10 @@ func TestSessionStore(t *testing.T, ss store.Store) {
 	t.Run("SessionUpdateDeviceId", func(t *testing.T) { testSessionUpdateDeviceId(t, ss) })
 	t.Run("SessionUpdateDeviceId2", func(t *testing.T) { testSessionUpdateDeviceId2(t, ss) })
 	t.Run("UpdateLastActivityAt", func(t *testing.T) { testSessionStoreUpdateLastActivityAt(t, ss) })
+	t.Run("UpdateLastActivityAtBatch", func(t *testing.T) { testSessionStoreUpdateLastActivityAtBatch(t, ss) })
+	t.Run("UpdateProps", func(t *testing.T) { testSessionStoreUpdateProps(t, ss) })
 	t.Run("SessionCount", func(t *testing.T) { testSessionCount(t, ss) })
+	t.Run("GetImpersonationSessions", func(t *testing.T) { testSessionGetImpersonationSessions(t, ss) })
 }
 
 func testSessionStoreSave(t *testing.T, ss store.Store) {
@@ -239,6 +244,35 @@ func testSessionStoreUpdateLastActivityAt(t *testing.T, ss store.Store) {
 
 }
 
+func testSessionStoreUpdateLastActivityAtBatch(t *testing.T, ss store.Store) {
+	s1 := store.Must(ss.Session().Save(&model.Session{UserId: model.NewId()})).(*model.Session)
+	s2 := store.Must(ss.Session().Save(&model.Session{UserId: model.NewId()})).(*model.Session)
+	s3 := store.Must(ss.Session().Save(&model.Session{UserId: model.NewId()})).(*model.Session)
+
+	require.Nil(t, (<-ss.Session().UpdateLastActivityAtBatch(map[string]int64{s1.Id: 1234567890, s2.Id: 1234567891})).Err)
+	require.Nil(t, (<-ss.Session().UpdateLastActivityAtBatch(map[string]int64{})).Err)
+
+	for id, expected := range map[string]int64{s1.Id: 1234567890, s2.Id: 1234567891, s3.Id: s3.LastActivityAt} {
+		r1 := <-ss.Session().Get(id)
+		require.Nil(t, r1.Err)
+		assert.Equal(t, expected, r1.Data.(*model.Session).LastActivityAt)
+	}
+}
+
+func testSessionStoreUpdateProps(t *testing.T, ss store.Store) {
+	s1 := model.Session{}
+	s1.UserId = model.NewId()
+	s1.AddProp(model.SESSION_PROP_PLATFORM, "Linux")
+	store.Must(ss.Session().Save(&s1))
+
+	props := model.StringMap{model.SESSION_PROP_PLATFORM: "Linux", model.SESSION_PROP_ANOMALY_DETECTED_AT: "1234567890"}
+	require.Nil(t, (<-ss.Session().UpdateProps(s1.Id, props)).Err)
+
+	r1 := <-ss.Session().Get(s1.Id)
+	require.Nil(t, r1.Err)
+	assert.Equal(t, props, r1.Data.(*model.Session).Props)
+}
+
 func testSessionCount(t *testing.T, ss store.Store) {
 	s1 := model.Session{}
 	s1.UserId = model.NewId()
@@ -254,7 +288,7 @@ func testSessionCount(t *testing.T, ss store.Store) {
 	}
 }
 
-func testSessionCleanup(t *testing.T, ss store.Store) {
+func testSessionPermanentDeleteExpiredBatch(t *testing.T, ss store.Store) {
 	now := model.GetMillis()
 
 	s1 := model.Session{}
@@ -277,20 +311,100 @@ func testSessionCleanup(t *testing.T, ss store.Store) {
 	s4.ExpiresAt = 2 // expired
 	store.Must(ss.Session().Save(&s4))
 
-	ss.Session().Cleanup(now, 1)
+	s5 := model.Session{}
+	s5.UserId = model.NewId()
+	s5.ExpiresAt = now - 1000 // expired, but more recently than the cutoff
+	store.Must(ss.Session().Save(&s5))
 
-	err := (<-ss.Session().Get(s1.Id)).Err
-	assert.Nil(t, err)
+	result := <-ss.Session().PermanentDeleteExpiredBatch(now-100000, 1)
+	require.Nil(t, result.Err)
+	deleted := result.Data.([]*model.Session)
+	require.Len(t, deleted, 1)
+	assert.Contains(t, []string{s3.Id, s4.Id}, deleted[0].Id)
+	assert.NotEmpty(t, deleted[0].Token)
 
-	err = (<-ss.Session().Get(s2.Id)).Err
-	assert.Nil(t, err)
+	result = <-ss.Session().PermanentDeleteExpiredBatch(now-100000, 10)
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]*model.Session), 1)
 
-	err = (<-ss.Session().Get(s3.Id)).Err
-	assert.NotNil(t, err)
+	result = <-ss.Session().PermanentDeleteExpiredBatch(now-100000, 10)
+	require.Nil(t, result.Err)
+	assert.Empty(t, result.Data.([]*model.Session))
 
-	err = (<-ss.Session().Get(s4.Id)).Err
-	assert.NotNil(t, err)
+	for _, id := range []string{s1.Id, s2.Id, s5.Id} {
+		assert.Nil(t, (<-ss.Session().Get(id)).Err)
+	}
+
+	for _, id := range []string{s3.Id, s4.Id} {
+		assert.NotNil(t, (<-ss.Session().Get(id)).Err)
+	}
 
 	store.Must(ss.Session().Remove(s1.Id))
 	store.Must(ss.Session().Remove(s2.Id))
+	store.Must(ss.Session().Remove(s5.Id))
+}
+
+func testSessionPermanentDeleteOrphanedBatch(t *testing.T, ss store.Store) {
+	user := store.Must(ss.User().Save(&model.User{Email: model.NewId() + "@nowhere.com"})).(*model.User)
+	defer ss.User().PermanentDelete(user.Id)
+
+	deactivated := store.Must(ss.User().Save(&model.User{Email: model.NewId() + "@nowhere.com", DeleteAt: model.GetMillis()})).(*model.User)
+	defer ss.User().PermanentDelete(deactivated.Id)
+
+	live := store.Must(ss.Session().Save(&model.Session{UserId: user.Id})).(*model.Session)
+	ofDeactivated := store.Must(ss.Session().Save(&model.Session{UserId: deactivated.Id})).(*model.Session)
+	ofDeleted := store.Must(ss.Session().Save(&model.Session{UserId: model.NewId()})).(*model.Session)
+
+	var deleted []string
+	for {
+		result := <-ss.Session().PermanentDeleteOrphanedBatch(100)
+		require.Nil(t, result.Err)
+		sessions := result.Data.([]*model.Session)
+		if len(sessions) == 0 {
+			break
+		}
+
+		for _, session := range sessions {
+			deleted = append(deleted, session.Id)
+		}
+	}
+
+	assert.Contains(t, deleted, ofDeactivated.Id)
+	assert.Contains(t, deleted, ofDeleted.Id)
+	assert.NotContains(t, deleted, live.Id)
+
+	assert.Nil(t, (<-ss.Session().Get(live.Id)).Err)
+	assert.NotNil(t, (<-ss.Session().Get(ofDeleted.Id)).Err)
+
+	store.Must(ss.Session().Remove(live.Id))
+}
+
+func testSessionGetImpersonationSessions(t *testing.T, ss store.Store) {
+	admin := model.Session{}
+	admin.UserId = model.NewId()
+	store.Must(ss.Session().Save(&admin))
+
+	s1 := model.Session{UserId: model.NewId(), IsImpersonation: true}
+	s1.AddProp(model.SESSION_PROP_IMPERSONATOR_ID, admin.UserId)
+	s1.AddProp(model.SESSION_PROP_IMPERSONATOR_SESSION_ID, admin.Id)
+	store.Must(ss.Session().Save(&s1))
+
+	// a session from another of the admin's sessions
+	s2 := model.Session{UserId: model.NewId(), IsImpersonation: true}
+	s2.AddProp(model.SESSION_PROP_IMPERSONATOR_ID, admin.UserId)
+	s2.AddProp(model.SESSION_PROP_IMPERSONATOR_SESSION_ID, model.NewId())
+	store.Must(ss.Session().Save(&s2))
+
+	result := <-ss.Session().GetImpersonationSessions(admin.Id)
+	require.Nil(t, result.Err)
+
+	sessions := result.Data.([]*model.Session)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, s1.Id, sessions[0].Id)
+	assert.True(t, sessions[0].IsImpersonation)
+	assert.Equal(t, admin.UserId, sessions[0].Props[model.SESSION_PROP_IMPERSONATOR_ID])
+
+	result = <-ss.Session().GetImpersonationSessions(s1.Id)
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]*model.Session), 0)
 }
@@ -0,0 +1,80 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package storetest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func TestShortPermalinkStore(t *testing.T, ss store.Store) {
+	t.Run("SaveAndGet", func(t *testing.T) { testShortPermalinkStoreSaveAndGet(t, ss) })
+	t.Run("GetLatestForPost", func(t *testing.T) { testShortPermalinkStoreGetLatestForPost(t, ss) })
+	t.Run("PermanentDeleteExpired", func(t *testing.T) { testShortPermalinkStorePermanentDeleteExpired(t, ss) })
+}
+
+func saveTestShortPermalink(t *testing.T, ss store.Store, postId, teamId string, expireAt int64) *model.ShortPermalink {
+	result := <-ss.ShortPermalink().Save(&model.ShortPermalink{PostId: postId, TeamId: teamId, ExpireAt: expireAt})
+	require.Nil(t, result.Err)
+	return result.Data.(*model.ShortPermalink)
+}
+
+func testShortPermalinkStoreSaveAndGet(t *testing.T, ss store.Store) {
+	link := saveTestShortPermalink(t, ss, model.NewId(), model.NewId(), model.GetMillis()+60000)
+	assert.Len(t, link.Code, model.SHORT_PERMALINK_CODE_LENGTH)
+
+	result := <-ss.ShortPermalink().Get(link.Code)
+	require.Nil(t, result.Err)
+	assert.Equal(t, link, result.Data.(*model.ShortPermalink))
+
+	result = <-ss.ShortPermalink().Get(model.NewRandomString(model.SHORT_PERMALINK_CODE_LENGTH))
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+
+	result = <-ss.ShortPermalink().Save(&model.ShortPermalink{PostId: model.NewId(), TeamId: model.NewId()})
+	assert.NotNil(t, result.Err, "a link has to expire")
+}
+
+func testShortPermalinkStoreGetLatestForPost(t *testing.T, ss store.Store) {
+	postId := model.NewId()
+	teamId := model.NewId()
+	now := model.GetMillis()
+
+	saveTestShortPermalink(t, ss, postId, teamId, now+1000)
+	latest := saveTestShortPermalink(t, ss, postId, teamId, now+2000)
+	saveTestShortPermalink(t, ss, postId, model.NewId(), now+3000)
+	saveTestShortPermalink(t, ss, model.NewId(), teamId, now+3000)
+
+	result := <-ss.ShortPermalink().GetLatestForPost(postId, teamId, now)
+	require.Nil(t, result.Err)
+	assert.Equal(t, latest.Code, result.Data.(*model.ShortPermalink).Code)
+
+	result = <-ss.ShortPermalink().GetLatestForPost(postId, teamId, now+2000)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+}
+
+func testShortPermalinkStorePermanentDeleteExpired(t *testing.T, ss store.Store) {
+	now := model.GetMillis()
+
+	expired := saveTestShortPermalink(t, ss, model.NewId(), model.NewId(), now+1)
+	current := saveTestShortPermalink(t, ss, model.NewId(), model.NewId(), now+60000)
+
+	result := <-ss.ShortPermalink().PermanentDeleteExpired(now + 1000)
+	require.Nil(t, result.Err)
+	assert.True(t, result.Data.(int64) >= 1)
+
+	result = <-ss.ShortPermalink().Get(expired.Code)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+
+	result = <-ss.ShortPermalink().Get(current.Code)
+	assert.Nil(t, result.Err)
+}
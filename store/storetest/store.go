@@ -44,6 +44,23 @@ type Store struct {
 	PluginStore               mocks.PluginStore
 	ChannelMemberHistoryStore mocks.ChannelMemberHistoryStore
 	RoleStore                 mocks.RoleStore
+	ThreadStore               mocks.ThreadStore
+	CustomGroupStore          mocks.CustomGroupStore
+	UserAttributeStore        mocks.UserAttributeStore
+	RetentionPolicyStore      mocks.RetentionPolicyStore
+	TermsOfServiceStore       mocks.TermsOfServiceStore
+	AnalyticsDailyStore       mocks.AnalyticsDailyStore
+	ProvisioningTokenStore    mocks.ProvisioningTokenStore
+	InviteLinkStore           mocks.InviteLinkStore
+	TeamInvitationStore       mocks.TeamInvitationStore
+	TeamTemplateStore         mocks.TeamTemplateStore
+	ShortPermalinkStore       mocks.ShortPermalinkStore
+	ConfigurationHistoryStore mocks.ConfigurationHistoryStore
+	FilePublicLinkStore       mocks.FilePublicLinkStore
+	FileStorageUsageStore     mocks.FileStorageUsageStore
+	BlockedDomainStore        mocks.BlockedDomainStore
+	WebSocketOutboxStore      mocks.WebSocketOutboxStore
+	PostIdempotencyKeyStore   mocks.PostIdempotencyKeyStore
 }
 
 func (s *Store) Team() store.TeamStore                         { return &s.TeamStore }
@@ -70,6 +87,44 @@ func (s *Store) Job() store.JobStore                           { return &s.JobSt
 func (s *Store) UserAccessToken() store.UserAccessTokenStore   { return &s.UserAccessTokenStore }
 func (s *Store) Plugin() store.PluginStore                     { return &s.PluginStore }
 func (s *Store) Role() store.RoleStore                         { return &s.RoleStore }
+func (s *Store) Thread() store.ThreadStore                     { return &s.ThreadStore }
+func (s *Store) CustomGroup() store.CustomGroupStore           { return &s.CustomGroupStore }
+func (s *Store) UserAttribute() store.UserAttributeStore       { return &s.UserAttributeStore }
+func (s *Store) RetentionPolicy() store.RetentionPolicyStore   { return &s.RetentionPolicyStore }
+func (s *Store) TermsOfService() store.TermsOfServiceStore     { return &s.TermsOfServiceStore }
+func (s *Store) AnalyticsDaily() store.AnalyticsDailyStore     { return &s.AnalyticsDailyStore }
+func (s *Store) ProvisioningToken() store.ProvisioningTokenStore {
+	return &s.ProvisioningTokenStore
+}
+func (s *Store) InviteLink() store.InviteLinkStore { return &s.InviteLinkStore }
+func (s *Store) TeamInvitation() store.TeamInvitationStore {
+	return &s.TeamInvitationStore
+}
+
+func (s *Store) TeamTemplate() store.TeamTemplateStore {
+	return &s.TeamTemplateStore
+}
+func (s *Store) ShortPermalink() store.ShortPermalinkStore {
+	return &s.ShortPermalinkStore
+}
+func (s *Store) ConfigurationHistory() store.ConfigurationHistoryStore {
+	return &s.ConfigurationHistoryStore
+}
+func (s *Store) FilePublicLink() store.FilePublicLinkStore {
+	return &s.FilePublicLinkStore
+}
+func (s *Store) FileStorageUsage() store.FileStorageUsageStore {
+	return &s.FileStorageUsageStore
+}
+func (s *Store) BlockedDomain() store.BlockedDomainStore {
+	return &s.BlockedDomainStore
+}
+func (s *Store) WebSocketOutbox() store.WebSocketOutboxStore {
+	return &s.WebSocketOutboxStore
+}
+func (s *Store) PostIdempotencyKey() store.PostIdempotencyKeyStore {
+	return &s.PostIdempotencyKeyStore
+}
 func (s *Store) ChannelMemberHistory() store.ChannelMemberHistoryStore {
 	return &s.ChannelMemberHistoryStore
 }
@@ -79,6 +134,10 @@ func (s *Store) DropAllTables()                { /* do nothing */ }
 func (s *Store) TotalMasterDbConnections() int { return 1 }
 func (s *Store) TotalReadDbConnections() int   { return 1 }
 func (s *Store) TotalSearchDbConnections() int { return 1 }
+func (s *Store) IsMasterHealthy() bool         { return true }
+func (s *Store) IsReadOnly() bool              { return false }
+func (s *Store) SetReadOnly(readOnly bool)     { /* do nothing */ }
+func (s *Store) IsFullUnicodeSupported() bool  { return true }
 
 func (s *Store) AssertExpectations(t mock.TestingT) bool {
 	return mock.AssertExpectationsForObjects(t,
@@ -107,5 +166,22 @@ func (s *Store) AssertExpectations(t mock.TestingT) bool {
 		&s.ChannelMemberHistoryStore,
 		&s.PluginStore,
 		&s.RoleStore,
+		&s.ThreadStore,
+		&s.CustomGroupStore,
+		&s.UserAttributeStore,
+		&s.RetentionPolicyStore,
+		&s.TermsOfServiceStore,
+		&s.AnalyticsDailyStore,
+		&s.ProvisioningTokenStore,
+		&s.InviteLinkStore,
+		&s.TeamInvitationStore,
+		&s.TeamTemplateStore,
+		&s.ShortPermalinkStore,
+		&s.ConfigurationHistoryStore,
+		&s.FilePublicLinkStore,
+		&s.FileStorageUsageStore,
+		&s.BlockedDomainStore,
+		&s.WebSocketOutboxStore,
+		&s.PostIdempotencyKeyStore,
 	)
 }
@@ -0,0 +1,110 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package storetest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func TestTeamInvitationStore(t *testing.T, ss store.Store) {
+	t.Run("SaveAndGet", func(t *testing.T) { testTeamInvitationStoreSaveAndGet(t, ss) })
+	t.Run("GetForTeam", func(t *testing.T) { testTeamInvitationStoreGetForTeam(t, ss) })
+	t.Run("UpdateStatus", func(t *testing.T) { testTeamInvitationStoreUpdateStatus(t, ss) })
+	t.Run("MarkBounced", func(t *testing.T) { testTeamInvitationStoreMarkBounced(t, ss) })
+}
+
+func newTestTeamInvitation(teamId string, email string) *model.TeamInvitation {
+	return model.NewTeamInvitation(teamId, model.NewId(), &model.Invite{Email: email, Role: model.INVITE_ROLE_MEMBER})
+}
+
+func testTeamInvitationStoreSaveAndGet(t *testing.T, ss store.Store) {
+	invitation := model.NewTeamInvitation(model.NewId(), model.NewId(), &model.Invite{
+		Email:    strings.ToLower(model.NewId()) + "@example.com",
+		Role:     model.INVITE_ROLE_GUEST,
+		Channels: []string{model.NewId(), model.NewId()},
+		Message:  "Welcome!",
+	})
+	invitation = store.Must(ss.TeamInvitation().Save(invitation)).(*model.TeamInvitation)
+	assert.Len(t, invitation.Id, 26)
+
+	result := <-ss.TeamInvitation().Get(invitation.Id)
+	require.Nil(t, result.Err)
+	assert.Equal(t, invitation, result.Data.(*model.TeamInvitation))
+
+	result = <-ss.TeamInvitation().Get(model.NewId())
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+
+	result = <-ss.TeamInvitation().Save(newTestTeamInvitation(model.NewId(), "junk"))
+	assert.NotNil(t, result.Err)
+}
+
+func testTeamInvitationStoreGetForTeam(t *testing.T, ss store.Store) {
+	teamId := model.NewId()
+
+	first := store.Must(ss.TeamInvitation().Save(newTestTeamInvitation(teamId, strings.ToLower(model.NewId())+"@example.com"))).(*model.TeamInvitation)
+	second := store.Must(ss.TeamInvitation().Save(newTestTeamInvitation(teamId, strings.ToLower(model.NewId())+"@example.com"))).(*model.TeamInvitation)
+	store.Must(ss.TeamInvitation().Save(newTestTeamInvitation(model.NewId(), strings.ToLower(model.NewId())+"@example.com")))
+
+	invitations := store.Must(ss.TeamInvitation().GetForTeam(teamId, 0, 10)).([]*model.TeamInvitation)
+	require.Len(t, invitations, 2)
+	assert.ElementsMatch(t, []string{first.Id, second.Id}, []string{invitations[0].Id, invitations[1].Id})
+
+	invitations = store.Must(ss.TeamInvitation().GetForTeam(teamId, 1, 10)).([]*model.TeamInvitation)
+	assert.Len(t, invitations, 1)
+
+	invitations = store.Must(ss.TeamInvitation().GetForTeam(model.NewId(), 0, 10)).([]*model.TeamInvitation)
+	assert.Len(t, invitations, 0)
+}
+
+func testTeamInvitationStoreUpdateStatus(t *testing.T, ss store.Store) {
+	invitation := store.Must(ss.TeamInvitation().Save(newTestTeamInvitation(model.NewId(), strings.ToLower(model.NewId())+"@example.com"))).(*model.TeamInvitation)
+
+	store.Must(ss.TeamInvitation().UpdateStatus(invitation.Id, model.TEAM_INVITATION_STATUS_FAILED, strings.Repeat("a", model.TEAM_INVITATION_REASON_MAX_RUNES+1), invitation.CreateAt+1))
+
+	updated := store.Must(ss.TeamInvitation().Get(invitation.Id)).(*model.TeamInvitation)
+	assert.Equal(t, model.TEAM_INVITATION_STATUS_FAILED, updated.Status)
+	assert.Len(t, updated.Reason, model.TEAM_INVITATION_REASON_MAX_RUNES, "the reason should be truncated")
+	assert.Equal(t, invitation.CreateAt+1, updated.UpdateAt)
+
+	store.Must(ss.TeamInvitation().UpdateStatus(invitation.Id, model.TEAM_INVITATION_STATUS_SENT, "", invitation.CreateAt+2))
+
+	updated = store.Must(ss.TeamInvitation().Get(invitation.Id)).(*model.TeamInvitation)
+	assert.Equal(t, model.TEAM_INVITATION_STATUS_SENT, updated.Status)
+	assert.Equal(t, "", updated.Reason)
+}
+
+func testTeamInvitationStoreMarkBounced(t *testing.T, ss store.Store) {
+	email := strings.ToLower(model.NewId()) + "@example.com"
+
+	sent := store.Must(ss.TeamInvitation().Save(newTestTeamInvitation(model.NewId(), email))).(*model.TeamInvitation)
+	store.Must(ss.TeamInvitation().UpdateStatus(sent.Id, model.TEAM_INVITATION_STATUS_SENT, "", model.GetMillis()))
+
+	// invitations that are still waiting to be sent can't have bounced yet
+	queued := store.Must(ss.TeamInvitation().Save(newTestTeamInvitation(model.NewId(), email))).(*model.TeamInvitation)
+
+	other := store.Must(ss.TeamInvitation().Save(newTestTeamInvitation(model.NewId(), strings.ToLower(model.NewId())+"@example.com"))).(*model.TeamInvitation)
+	store.Must(ss.TeamInvitation().UpdateStatus(other.Id, model.TEAM_INVITATION_STATUS_SENT, "", model.GetMillis()))
+
+	count := store.Must(ss.TeamInvitation().MarkBounced(email, "mailbox full", model.GetMillis())).(int64)
+	assert.Equal(t, int64(1), count)
+
+	bounced := store.Must(ss.TeamInvitation().Get(sent.Id)).(*model.TeamInvitation)
+	assert.Equal(t, model.TEAM_INVITATION_STATUS_FAILED, bounced.Status)
+	assert.Equal(t, "mailbox full", bounced.Reason)
+
+	assert.Equal(t, model.TEAM_INVITATION_STATUS_QUEUED, store.Must(ss.TeamInvitation().Get(queued.Id)).(*model.TeamInvitation).Status)
+	assert.Equal(t, model.TEAM_INVITATION_STATUS_SENT, store.Must(ss.TeamInvitation().Get(other.Id)).(*model.TeamInvitation).Status)
+
+	count = store.Must(ss.TeamInvitation().MarkBounced(email, "mailbox full", model.GetMillis())).(int64)
+	assert.Equal(t, int64(0), count)
+}
@@ -7,6 +7,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/store"
 )
@@ -31,9 +34,13 @@ func TestTeamStore(t *testing.T, ss store.Store) {
 	t.Run("GetTeamMember", func(t *testing.T) { testGetTeamMember(t, ss) })
 	t.Run("GetTeamMembersByIds", func(t *testing.T) { testGetTeamMembersByIds(t, ss) })
 	t.Run("MemberCount", func(t *testing.T) { testTeamStoreMemberCount(t, ss) })
+	t.Run("MemberCounts", func(t *testing.T) { testTeamStoreMemberCounts(t, ss) })
 	t.Run("GetChannelUnreadsForAllTeams", func(t *testing.T) { testGetChannelUnreadsForAllTeams(t, ss) })
 	t.Run("GetChannelUnreadsForTeam", func(t *testing.T) { testGetChannelUnreadsForTeam(t, ss) })
 	t.Run("UpdateLastTeamIconUpdate", func(t *testing.T) { testUpdateLastTeamIconUpdate(t, ss) })
+	t.Run("DefaultChannels", func(t *testing.T) { testTeamStoreDefaultChannels(t, ss) })
+	t.Run("FilteredWords", func(t *testing.T) { testTeamStoreFilteredWords(t, ss) })
+	t.Run("GetDataCounts", func(t *testing.T) { testTeamStoreGetDataCounts(t, ss) })
 }
 
 func testTeamStoreSave(t *testing.T, ss store.Store) {
@@ -865,7 +872,7 @@ func testTeamStoreMemberCount(t *testing.T, ss store.Store) {
 	u2.DeleteAt = 1
 	store.Must(ss.User().Save(u2))
 
-	teamId1 := model.NewId()
+	teamId1 := saveMemberCountTeam(t, ss).Id
 	m1 := &model.TeamMember{TeamId: teamId1, UserId: u1.Id}
 	store.Must(ss.Team().SaveMember(m1, -1))
 
@@ -1029,3 +1036,99 @@ func testUpdateLastTeamIconUpdate(t *testing.T, ss store.Store) {
 		t.Fatal("LastTeamIconUpdate not updated")
 	}
 }
+
+func testTeamStoreDefaultChannels(t *testing.T, ss store.Store) {
+	o1 := &model.Team{}
+	o1.DisplayName = "Display Name"
+	o1.Name = "z-z-z" + model.NewId() + "b"
+	o1.Email = model.NewId() + "@nowhere.com"
+	o1.Type = model.TEAM_OPEN
+	o1 = store.Must(ss.Team().Save(o1)).(*model.Team)
+
+	if channelIds := store.Must(ss.Team().GetDefaultChannels(o1.Id)).([]string); len(channelIds) != 0 {
+		t.Fatal("should have no default channels", channelIds)
+	}
+
+	channelId1 := model.NewId()
+	channelId2 := model.NewId()
+	store.Must(ss.Team().UpdateDefaultChannels(o1.Id, []string{channelId1, channelId2}))
+
+	if channelIds := store.Must(ss.Team().GetDefaultChannels(o1.Id)).([]string); len(channelIds) != 2 {
+		t.Fatal("should have two default channels", channelIds)
+	}
+
+	store.Must(ss.Team().UpdateDefaultChannels(o1.Id, []string{channelId2}))
+
+	if channelIds := store.Must(ss.Team().GetDefaultChannels(o1.Id)).([]string); len(channelIds) != 1 || channelIds[0] != channelId2 {
+		t.Fatal("should have replaced the default channels", channelIds)
+	}
+
+	if channelIds := store.Must(ss.Team().GetDefaultChannels(model.NewId())).([]string); len(channelIds) != 0 {
+		t.Fatal("other teams should have no default channels", channelIds)
+	}
+
+	store.Must(ss.Team().PermanentDelete(o1.Id))
+
+	if channelIds := store.Must(ss.Team().GetDefaultChannels(o1.Id)).([]string); len(channelIds) != 0 {
+		t.Fatal("default channels should be removed with the team", channelIds)
+	}
+}
+
+func testTeamStoreFilteredWords(t *testing.T, ss store.Store) {
+	o1 := &model.Team{}
+	o1.DisplayName = "Display Name"
+	o1.Name = "z-z-z" + model.NewId() + "b"
+	o1.Email = model.NewId() + "@nowhere.com"
+	o1.Type = model.TEAM_OPEN
+	o1 = store.Must(ss.Team().Save(o1)).(*model.Team)
+
+	if words := store.Must(ss.Team().GetFilteredWords(o1.Id)).([]string); len(words) != 0 {
+		t.Fatal("should have no filtered words", words)
+	}
+
+	store.Must(ss.Team().UpdateFilteredWords(o1.Id, []string{"zebra", "äpfel"}))
+
+	if words := store.Must(ss.Team().GetFilteredWords(o1.Id)).([]string); len(words) != 2 {
+		t.Fatal("should have two filtered words", words)
+	}
+
+	store.Must(ss.Team().UpdateFilteredWords(o1.Id, []string{"zebra"}))
+
+	if words := store.Must(ss.Team().GetFilteredWords(o1.Id)).([]string); len(words) != 1 || words[0] != "zebra" {
+		t.Fatal("should have replaced the cached filtered words", words)
+	}
+
+	if words := store.Must(ss.Team().GetFilteredWords(model.NewId())).([]string); len(words) != 0 {
+		t.Fatal("other teams should have no filtered words", words)
+	}
+
+	store.Must(ss.Team().PermanentDelete(o1.Id))
+
+	if words := store.Must(ss.Team().GetFilteredWords(o1.Id)).([]string); len(words) != 0 {
+		t.Fatal("filtered words should be removed with the team", words)
+	}
+}
+
+func testTeamStoreGetDataCounts(t *testing.T, ss store.Store) {
+	team := store.Must(ss.Team().Save(&model.Team{
+		DisplayName: "DisplayName",
+		Name:        "zz" + model.NewId() + "b",
+		Email:       model.NewId() + "@nowhere.com",
+		Type:        model.TEAM_OPEN,
+	})).(*model.Team)
+
+	store.Must(ss.Channel().Save(&model.Channel{TeamId: team.Id, DisplayName: "Name", Name: "zz" + model.NewId() + "b", Type: model.CHANNEL_OPEN}, -1))
+	store.Must(ss.Team().SaveMember(&model.TeamMember{TeamId: team.Id, UserId: model.NewId()}, -1))
+	store.Must(ss.Team().SaveMember(&model.TeamMember{TeamId: team.Id, UserId: model.NewId()}, -1))
+	store.Must(ss.Webhook().SaveIncoming(&model.IncomingWebhook{TeamId: team.Id, ChannelId: model.NewId(), UserId: model.NewId()}))
+
+	result := <-ss.Team().GetDataCounts(team.Id)
+	require.Nil(t, result.Err)
+	assert.Equal(t, map[string]int64{
+		"Channels":         1,
+		"TeamMembers":      2,
+		"Commands":         0,
+		"IncomingWebhooks": 1,
+		"OutgoingWebhooks": 0,
+	}, result.Data.(map[string]int64))
+}
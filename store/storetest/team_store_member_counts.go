@@ -0,0 +1,115 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package storetest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func assertTeamMemberCount(t *testing.T, ss store.Store, teamId string, memberCount int64) {
+	result := <-ss.Team().GetActiveMemberCount(teamId)
+	require.Nil(t, result.Err)
+	assert.Equal(t, memberCount, result.Data.(int64), "wrong member count")
+}
+
+func saveMemberCountTeam(t *testing.T, ss store.Store) *model.Team {
+	return store.Must(ss.Team().Save(&model.Team{
+		DisplayName: "Team",
+		Name:        "zz" + model.NewId() + "b",
+		Email:       model.NewId() + "@nowhere.com",
+		Type:        model.TEAM_OPEN,
+		MemberCount: 10,
+	})).(*model.Team)
+}
+
+func testTeamStoreMemberCounts(t *testing.T, ss store.Store) {
+	team := saveMemberCountTeam(t, ss)
+	otherTeam := saveMemberCountTeam(t, ss)
+
+	t.Run("new teams have no members", func(t *testing.T) {
+		assertTeamMemberCount(t, ss, team.Id, 0)
+	})
+
+	user := store.Must(ss.User().Save(&model.User{Email: model.NewId()})).(*model.User)
+	user2 := store.Must(ss.User().Save(&model.User{Email: model.NewId()})).(*model.User)
+
+	store.Must(ss.Team().SaveMember(&model.TeamMember{TeamId: team.Id, UserId: user.Id}, -1))
+	store.Must(ss.Team().SaveMember(&model.TeamMember{TeamId: team.Id, UserId: user2.Id}, -1))
+	store.Must(ss.Team().SaveMember(&model.TeamMember{TeamId: otherTeam.Id, UserId: user.Id}, -1))
+
+	t.Run("joining", func(t *testing.T) {
+		assertTeamMemberCount(t, ss, team.Id, 2)
+		assertTeamMemberCount(t, ss, otherTeam.Id, 1)
+	})
+
+	t.Run("updating the team keeps the count", func(t *testing.T) {
+		team.DisplayName = "Renamed"
+		store.Must(ss.Team().Update(team))
+
+		assertTeamMemberCount(t, ss, team.Id, 2)
+	})
+
+	t.Run("deactivation", func(t *testing.T) {
+		user.DeleteAt = model.GetMillis()
+		store.Must(ss.User().Update(user, true))
+		assertTeamMemberCount(t, ss, team.Id, 1)
+		assertTeamMemberCount(t, ss, otherTeam.Id, 0)
+
+		user.DeleteAt = 0
+		store.Must(ss.User().Update(user, true))
+		assertTeamMemberCount(t, ss, team.Id, 2)
+		assertTeamMemberCount(t, ss, otherTeam.Id, 1)
+	})
+
+	t.Run("leaving", func(t *testing.T) {
+		member := store.Must(ss.Team().GetMember(team.Id, user2.Id)).(*model.TeamMember)
+		member.DeleteAt = model.GetMillis()
+		store.Must(ss.Team().UpdateMember(member))
+		assertTeamMemberCount(t, ss, team.Id, 1)
+
+		member.DeleteAt = 0
+		store.Must(ss.Team().UpdateMember(member))
+		assertTeamMemberCount(t, ss, team.Id, 2)
+
+		store.Must(ss.Team().RemoveMember(team.Id, user2.Id))
+		assertTeamMemberCount(t, ss, team.Id, 1)
+
+		// Removing someone who isn't a member changes nothing
+		store.Must(ss.Team().RemoveMember(team.Id, user2.Id))
+		assertTeamMemberCount(t, ss, team.Id, 1)
+	})
+
+	t.Run("getting every team's count", func(t *testing.T) {
+		counts := store.Must(ss.Team().GetAllMemberCounts()).([]*model.TeamSeatCount)
+
+		found := 0
+		for _, count := range counts {
+			if count.TeamId == team.Id || count.TeamId == otherTeam.Id {
+				assert.Equal(t, int64(1), count.ActiveMemberCount)
+				found++
+			}
+		}
+		assert.Equal(t, 2, found)
+	})
+
+	t.Run("removing all of a user's memberships", func(t *testing.T) {
+		store.Must(ss.Team().RemoveAllMembersByUser(user.Id))
+		assertTeamMemberCount(t, ss, team.Id, 0)
+		assertTeamMemberCount(t, ss, otherTeam.Id, 0)
+	})
+
+	t.Run("removing all of a team's members", func(t *testing.T) {
+		store.Must(ss.Team().SaveMember(&model.TeamMember{TeamId: otherTeam.Id, UserId: user2.Id}, -1))
+		assertTeamMemberCount(t, ss, otherTeam.Id, 1)
+
+		store.Must(ss.Team().RemoveAllMembersByTeam(otherTeam.Id))
+		assertTeamMemberCount(t, ss, otherTeam.Id, 0)
+	})
+}
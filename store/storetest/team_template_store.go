@@ -0,0 +1,104 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package storetest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func TestTeamTemplateStore(t *testing.T, ss store.Store) {
+	t.Run("SaveAndGet", func(t *testing.T) { testTeamTemplateStoreSaveAndGet(t, ss) })
+	t.Run("Update", func(t *testing.T) { testTeamTemplateStoreUpdate(t, ss) })
+	t.Run("GetAll", func(t *testing.T) { testTeamTemplateStoreGetAll(t, ss) })
+	t.Run("Delete", func(t *testing.T) { testTeamTemplateStoreDelete(t, ss) })
+}
+
+func testTeamTemplateStoreSaveAndGet(t *testing.T, ss store.Store) {
+	template := store.Must(ss.TeamTemplate().Save(&model.TeamTemplate{
+		Name:            "project-" + model.NewId(),
+		CreatorId:       model.NewId(),
+		Team:            &model.Team{Type: model.TEAM_INVITE},
+		Channels:        []*model.TeamTemplateChannel{{Name: "standup", DisplayName: "Standup", Type: model.CHANNEL_OPEN, Header: "Every day at 9"}},
+		DefaultChannels: []string{"standup"},
+		AdminEmails:     []string{"lead@example.com"},
+	})).(*model.TeamTemplate)
+	defer ss.TeamTemplate().Delete(template.Id)
+	assert.Len(t, template.Id, 26)
+
+	result := <-ss.TeamTemplate().Get(template.Id)
+	require.Nil(t, result.Err)
+	assert.Equal(t, template, result.Data.(*model.TeamTemplate))
+
+	result = <-ss.TeamTemplate().GetByName(template.Name)
+	require.Nil(t, result.Err)
+	assert.Equal(t, template, result.Data.(*model.TeamTemplate))
+
+	result = <-ss.TeamTemplate().Get(model.NewId())
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+
+	result = <-ss.TeamTemplate().GetByName(model.NewId())
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+
+	result = <-ss.TeamTemplate().Save(&model.TeamTemplate{Name: template.Name, CreatorId: model.NewId()})
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "store.sql_team_template.save.name_exists.app_error", result.Err.Id)
+
+	result = <-ss.TeamTemplate().Save(&model.TeamTemplate{Name: "project-" + model.NewId()})
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "model.team_template.is_valid.creator_id.app_error", result.Err.Id)
+}
+
+func testTeamTemplateStoreUpdate(t *testing.T, ss store.Store) {
+	template := store.Must(ss.TeamTemplate().Save(&model.TeamTemplate{Name: "project-" + model.NewId(), CreatorId: model.NewId()})).(*model.TeamTemplate)
+	defer ss.TeamTemplate().Delete(template.Id)
+
+	template.Description = "A team for each project"
+	template.AdminEmails = []string{"lead@example.com"}
+	store.Must(ss.TeamTemplate().Update(template))
+
+	updated := store.Must(ss.TeamTemplate().Get(template.Id)).(*model.TeamTemplate)
+	assert.Equal(t, "A team for each project", updated.Description)
+	assert.Equal(t, []string{"lead@example.com"}, updated.AdminEmails)
+
+	result := <-ss.TeamTemplate().Update(&model.TeamTemplate{Id: model.NewId(), Name: "project-" + model.NewId(), CreatorId: model.NewId(), CreateAt: 1})
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+}
+
+func testTeamTemplateStoreGetAll(t *testing.T, ss store.Store) {
+	template1 := store.Must(ss.TeamTemplate().Save(&model.TeamTemplate{Name: "project-" + model.NewId(), CreatorId: model.NewId()})).(*model.TeamTemplate)
+	template2 := store.Must(ss.TeamTemplate().Save(&model.TeamTemplate{Name: "project-" + model.NewId(), CreatorId: model.NewId()})).(*model.TeamTemplate)
+	defer ss.TeamTemplate().Delete(template1.Id)
+	defer ss.TeamTemplate().Delete(template2.Id)
+
+	templates := store.Must(ss.TeamTemplate().GetAll(0, 1000)).([]*model.TeamTemplate)
+	ids := make([]string, 0, len(templates))
+	for _, template := range templates {
+		ids = append(ids, template.Id)
+	}
+	assert.Contains(t, ids, template1.Id)
+	assert.Contains(t, ids, template2.Id)
+
+	templates = store.Must(ss.TeamTemplate().GetAll(0, 1)).([]*model.TeamTemplate)
+	assert.Len(t, templates, 1)
+}
+
+func testTeamTemplateStoreDelete(t *testing.T, ss store.Store) {
+	template := store.Must(ss.TeamTemplate().Save(&model.TeamTemplate{Name: "project-" + model.NewId(), CreatorId: model.NewId()})).(*model.TeamTemplate)
+
+	store.Must(ss.TeamTemplate().Delete(template.Id))
+
+	result := <-ss.TeamTemplate().Get(template.Id)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package storetest
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func TestTermsOfServiceStore(t *testing.T, ss store.Store) {
+	t.Run("SaveAndGet", func(t *testing.T) { testTermsOfServiceStoreSaveAndGet(t, ss) })
+	t.Run("GetLatest", func(t *testing.T) { testTermsOfServiceStoreGetLatest(t, ss) })
+	t.Run("UserTermsOfService", func(t *testing.T) { testTermsOfServiceStoreUserTermsOfService(t, ss) })
+}
+
+func testTermsOfServiceStoreSaveAndGet(t *testing.T, ss store.Store) {
+	terms := &model.TermsOfService{UserId: model.NewId(), Text: "You must behave"}
+
+	result := <-ss.TermsOfService().Save(terms)
+	require.Nil(t, result.Err)
+	assert.Len(t, terms.Id, 26)
+
+	result = <-ss.TermsOfService().Save(terms)
+	assert.NotNil(t, result.Err)
+
+	result = <-ss.TermsOfService().Save(&model.TermsOfService{UserId: model.NewId()})
+	assert.NotNil(t, result.Err)
+
+	result = <-ss.TermsOfService().Get(terms.Id)
+	require.Nil(t, result.Err)
+	assert.Equal(t, terms, result.Data.(*model.TermsOfService))
+
+	result = <-ss.TermsOfService().Get(model.NewId())
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+}
+
+func testTermsOfServiceStoreGetLatest(t *testing.T, ss store.Store) {
+	first := store.Must(ss.TermsOfService().Save(&model.TermsOfService{UserId: model.NewId(), Text: "First"})).(*model.TermsOfService)
+
+	time.Sleep(2 * time.Millisecond)
+
+	second := store.Must(ss.TermsOfService().Save(&model.TermsOfService{UserId: model.NewId(), Text: "Second"})).(*model.TermsOfService)
+
+	result := <-ss.TermsOfService().GetLatest()
+	require.Nil(t, result.Err)
+	assert.Equal(t, second.Id, result.Data.(*model.TermsOfService).Id)
+	assert.NotEqual(t, first.Id, result.Data.(*model.TermsOfService).Id)
+}
+
+func testTermsOfServiceStoreUserTermsOfService(t *testing.T, ss store.Store) {
+	userId := model.NewId()
+
+	result := <-ss.TermsOfService().GetUserTermsOfService(userId)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+
+	firstId := model.NewId()
+	result = <-ss.TermsOfService().SaveUserTermsOfService(&model.UserTermsOfService{UserId: userId, TermsOfServiceId: firstId})
+	require.Nil(t, result.Err)
+
+	result = <-ss.TermsOfService().GetUserTermsOfService(userId)
+	require.Nil(t, result.Err)
+	assert.Equal(t, firstId, result.Data.(*model.UserTermsOfService).TermsOfServiceId)
+
+	secondId := model.NewId()
+	result = <-ss.TermsOfService().SaveUserTermsOfService(&model.UserTermsOfService{UserId: userId, TermsOfServiceId: secondId})
+	require.Nil(t, result.Err)
+
+	result = <-ss.TermsOfService().GetUserTermsOfService(userId)
+	require.Nil(t, result.Err)
+	assert.Equal(t, secondId, result.Data.(*model.UserTermsOfService).TermsOfServiceId)
+
+	result = <-ss.TermsOfService().SaveUserTermsOfService(&model.UserTermsOfService{UserId: userId})
+	assert.NotNil(t, result.Err)
+}
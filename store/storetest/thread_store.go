@@ -0,0 +1,272 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package storetest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func TestThreadStore(t *testing.T, ss store.Store) {
+	t.Run("SaveAndGet", func(t *testing.T) { testThreadStoreSaveAndGet(t, ss) })
+	t.Run("Memberships", func(t *testing.T) { testThreadStoreMemberships(t, ss) })
+	t.Run("GetThreadsForUser", func(t *testing.T) { testThreadStoreGetThreadsForUser(t, ss) })
+	t.Run("MarkAllAsReadForUser", func(t *testing.T) { testThreadStoreMarkAllAsReadForUser(t, ss) })
+	t.Run("Delete", func(t *testing.T) { testThreadStoreDelete(t, ss) })
+	t.Run("LeftChannel", func(t *testing.T) { testThreadStoreLeftChannel(t, ss) })
+}
+
+func makeThreadForTest(t *testing.T, ss store.Store, teamId string, userId string, replies int) (*model.Channel, *model.Thread) {
+	channel := store.Must(ss.Channel().Save(&model.Channel{
+		TeamId:      teamId,
+		DisplayName: "Display " + model.NewId(),
+		Name:        "zz" + model.NewId() + "b",
+		Type:        model.CHANNEL_OPEN,
+	}, -1)).(*model.Channel)
+
+	store.Must(ss.Channel().SaveMember(&model.ChannelMember{
+		ChannelId:   channel.Id,
+		UserId:      userId,
+		NotifyProps: model.GetDefaultChannelNotifyProps(),
+	}))
+
+	root := store.Must(ss.Post().Save(&model.Post{
+		ChannelId: channel.Id,
+		UserId:    userId,
+		Message:   "root",
+	})).(*model.Post)
+
+	thread := &model.Thread{
+		PostId:       root.Id,
+		ChannelId:    channel.Id,
+		Participants: model.StringArray{userId},
+	}
+
+	for i := 0; i < replies; i++ {
+		reply := store.Must(ss.Post().Save(&model.Post{
+			ChannelId: channel.Id,
+			UserId:    userId,
+			RootId:    root.Id,
+			ParentId:  root.Id,
+			Message:   "reply",
+			CreateAt:  root.CreateAt + int64(i+1),
+		})).(*model.Post)
+
+		thread.ReplyCount++
+		thread.LastReplyAt = reply.CreateAt
+	}
+
+	store.Must(ss.Thread().Save(thread))
+
+	return channel, thread
+}
+
+func testThreadStoreSaveAndGet(t *testing.T, ss store.Store) {
+	_, thread := makeThreadForTest(t, ss, model.NewId(), model.NewId(), 2)
+
+	result := <-ss.Thread().Save(thread)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "store.sql_thread.save.exists.app_error", result.Err.Id)
+
+	result = <-ss.Thread().Get(thread.PostId)
+	require.Nil(t, result.Err)
+	assert.Equal(t, thread, result.Data.(*model.Thread))
+
+	thread.ReplyCount = 3
+	thread.Participants = append(thread.Participants, model.NewId())
+	store.Must(ss.Thread().Update(thread))
+
+	result = <-ss.Thread().Get(thread.PostId)
+	require.Nil(t, result.Err)
+	assert.Equal(t, thread, result.Data.(*model.Thread))
+
+	result = <-ss.Thread().Get(model.NewId())
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+
+	result = <-ss.Thread().Save(&model.Thread{PostId: model.NewId()})
+	assert.NotNil(t, result.Err)
+}
+
+func testThreadStoreMemberships(t *testing.T, ss store.Store) {
+	userId := model.NewId()
+	_, thread := makeThreadForTest(t, ss, model.NewId(), userId, 2)
+
+	membership := &model.ThreadMembership{
+		PostId:     thread.PostId,
+		UserId:     userId,
+		Following:  true,
+		LastViewed: thread.LastReplyAt - 1,
+	}
+	store.Must(ss.Thread().SaveMembership(membership))
+
+	result := <-ss.Thread().SaveMembership(membership)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "store.sql_thread.save_membership.exists.app_error", result.Err.Id)
+
+	store.Must(ss.Thread().IncrementMentionCount(userId, thread.PostId))
+	store.Must(ss.Thread().IncrementMentionCount(userId, thread.PostId))
+
+	result = <-ss.Thread().GetMembershipForUser(userId, thread.PostId)
+	require.Nil(t, result.Err)
+	assert.Equal(t, int64(2), result.Data.(*model.ThreadMembership).UnreadMentions)
+
+	result = <-ss.Thread().GetThreadForUser(userId, thread.PostId)
+	require.Nil(t, result.Err)
+	response := result.Data.(*model.ThreadResponse)
+	assert.Equal(t, thread.ReplyCount, response.ReplyCount)
+	assert.Equal(t, membership.LastViewed, response.LastViewedAt)
+	assert.Equal(t, int64(1), response.UnreadReplies)
+	assert.Equal(t, int64(2), response.UnreadMentions)
+
+	membership.Following = false
+	membership.LastViewed = thread.LastReplyAt
+	membership.UnreadMentions = 0
+	store.Must(ss.Thread().UpdateMembership(membership))
+
+	result = <-ss.Thread().GetThreadForUser(userId, thread.PostId)
+	require.Nil(t, result.Err)
+	assert.Equal(t, int64(0), result.Data.(*model.ThreadResponse).UnreadReplies)
+
+	result = <-ss.Thread().GetMembershipsForThread(thread.PostId)
+	require.Nil(t, result.Err)
+	require.Len(t, result.Data.([]*model.ThreadMembership), 1)
+	assert.False(t, result.Data.([]*model.ThreadMembership)[0].Following)
+
+	result = <-ss.Thread().GetMembershipForUser(model.NewId(), thread.PostId)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+
+	result = <-ss.Thread().GetThreadForUser(model.NewId(), thread.PostId)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+}
+
+func testThreadStoreGetThreadsForUser(t *testing.T, ss store.Store) {
+	teamId := model.NewId()
+	userId := model.NewId()
+
+	_, thread1 := makeThreadForTest(t, ss, teamId, userId, 1)
+	_, thread2 := makeThreadForTest(t, ss, teamId, userId, 2)
+	_, otherTeamThread := makeThreadForTest(t, ss, model.NewId(), userId, 1)
+	_, unfollowedThread := makeThreadForTest(t, ss, teamId, userId, 1)
+
+	store.Must(ss.Thread().SaveMembership(&model.ThreadMembership{PostId: thread1.PostId, UserId: userId, Following: true, LastViewed: thread1.LastReplyAt}))
+	store.Must(ss.Thread().SaveMembership(&model.ThreadMembership{PostId: thread2.PostId, UserId: userId, Following: true, UnreadMentions: 3}))
+	store.Must(ss.Thread().SaveMembership(&model.ThreadMembership{PostId: otherTeamThread.PostId, UserId: userId, Following: true}))
+	store.Must(ss.Thread().SaveMembership(&model.ThreadMembership{PostId: unfollowedThread.PostId, UserId: userId, Following: false}))
+
+	result := <-ss.Thread().GetThreadsForUser(userId, teamId, 0, 10)
+	require.Nil(t, result.Err)
+	threads := result.Data.(*model.Threads)
+
+	assert.Equal(t, int64(2), threads.Total)
+	assert.Equal(t, int64(1), threads.TotalUnreadThreads)
+	assert.Equal(t, int64(3), threads.TotalUnreadMentions)
+	require.Len(t, threads.Threads, 2)
+	assert.Equal(t, thread2.PostId, threads.Threads[0].PostId)
+	assert.Equal(t, int64(2), threads.Threads[0].UnreadReplies)
+	assert.Equal(t, thread1.PostId, threads.Threads[1].PostId)
+	assert.Equal(t, int64(0), threads.Threads[1].UnreadReplies)
+
+	result = <-ss.Thread().GetThreadsForUser(userId, teamId, 1, 10)
+	require.Nil(t, result.Err)
+	threads = result.Data.(*model.Threads)
+	assert.Equal(t, int64(2), threads.Total)
+	require.Len(t, threads.Threads, 1)
+	assert.Equal(t, thread1.PostId, threads.Threads[0].PostId)
+
+	result = <-ss.Thread().GetThreadsForUser(model.NewId(), teamId, 0, 10)
+	require.Nil(t, result.Err)
+	assert.Equal(t, int64(0), result.Data.(*model.Threads).Total)
+	assert.Len(t, result.Data.(*model.Threads).Threads, 0)
+}
+
+func testThreadStoreMarkAllAsReadForUser(t *testing.T, ss store.Store) {
+	teamId := model.NewId()
+	userId := model.NewId()
+
+	_, thread := makeThreadForTest(t, ss, teamId, userId, 2)
+	_, otherTeamThread := makeThreadForTest(t, ss, model.NewId(), userId, 1)
+
+	store.Must(ss.Thread().SaveMembership(&model.ThreadMembership{PostId: thread.PostId, UserId: userId, Following: true, UnreadMentions: 2}))
+	store.Must(ss.Thread().SaveMembership(&model.ThreadMembership{PostId: otherTeamThread.PostId, UserId: userId, Following: true, UnreadMentions: 1}))
+
+	now := model.GetMillis()
+	store.Must(ss.Thread().MarkAllAsReadForUser(userId, teamId, now))
+
+	membership := store.Must(ss.Thread().GetMembershipForUser(userId, thread.PostId)).(*model.ThreadMembership)
+	assert.Equal(t, now, membership.LastViewed)
+	assert.Equal(t, int64(0), membership.UnreadMentions)
+
+	membership = store.Must(ss.Thread().GetMembershipForUser(userId, otherTeamThread.PostId)).(*model.ThreadMembership)
+	assert.Equal(t, int64(0), membership.LastViewed)
+	assert.Equal(t, int64(1), membership.UnreadMentions, "threads in other teams shouldn't be marked as read")
+
+	store.Must(ss.Thread().MarkAllAsReadForUser(userId, "", now))
+
+	membership = store.Must(ss.Thread().GetMembershipForUser(userId, otherTeamThread.PostId)).(*model.ThreadMembership)
+	assert.Equal(t, now, membership.LastViewed)
+	assert.Equal(t, int64(0), membership.UnreadMentions)
+}
+
+func testThreadStoreDelete(t *testing.T, ss store.Store) {
+	userId := model.NewId()
+	_, thread := makeThreadForTest(t, ss, model.NewId(), userId, 1)
+	store.Must(ss.Thread().SaveMembership(&model.ThreadMembership{PostId: thread.PostId, UserId: userId, Following: true}))
+
+	store.Must(ss.Thread().Delete(thread.PostId))
+
+	result := <-ss.Thread().Get(thread.PostId)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+
+	result = <-ss.Thread().GetMembershipsForThread(thread.PostId)
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]*model.ThreadMembership), 0)
+}
+
+func testThreadStoreLeftChannel(t *testing.T, ss store.Store) {
+	teamId := model.NewId()
+	userId := model.NewId()
+	otherUserId := model.NewId()
+
+	channel, thread := makeThreadForTest(t, ss, teamId, userId, 1)
+	_, otherThread := makeThreadForTest(t, ss, teamId, userId, 1)
+	store.Must(ss.Channel().SaveMember(&model.ChannelMember{ChannelId: channel.Id, UserId: otherUserId, NotifyProps: model.GetDefaultChannelNotifyProps()}))
+
+	store.Must(ss.Thread().SaveMembership(&model.ThreadMembership{PostId: thread.PostId, UserId: userId, Following: true}))
+	store.Must(ss.Thread().SaveMembership(&model.ThreadMembership{PostId: otherThread.PostId, UserId: userId, Following: true}))
+	store.Must(ss.Thread().SaveMembership(&model.ThreadMembership{PostId: thread.PostId, UserId: otherUserId, Following: true}))
+
+	store.Must(ss.Channel().RemoveMember(channel.Id, userId))
+
+	// Memberships left behind by a user who isn't in the channel anymore don't give them the thread
+	threads := store.Must(ss.Thread().GetThreadsForUser(userId, teamId, 0, 10)).(*model.Threads)
+	assert.Equal(t, int64(1), threads.Total)
+	require.Len(t, threads.Threads, 1)
+	assert.Equal(t, otherThread.PostId, threads.Threads[0].PostId)
+
+	result := <-ss.Thread().GetThreadForUser(userId, thread.PostId)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+
+	store.Must(ss.Thread().DeleteMembershipsForUserInChannel(userId, channel.Id))
+
+	result = <-ss.Thread().GetMembershipForUser(userId, thread.PostId)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+
+	result = <-ss.Thread().GetMembershipForUser(userId, otherThread.PostId)
+	assert.Nil(t, result.Err, "memberships of threads in other channels should be kept")
+
+	result = <-ss.Thread().GetThreadForUser(otherUserId, thread.PostId)
+	assert.Nil(t, result.Err, "the other members of the channel should keep following the thread")
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package storetest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func TestUserAttributeStore(t *testing.T, ss store.Store) {
+	t.Run("SaveAndGetField", func(t *testing.T) { testUserAttributeStoreSaveAndGetField(t, ss) })
+	t.Run("UpdateField", func(t *testing.T) { testUserAttributeStoreUpdateField(t, ss) })
+	t.Run("Values", func(t *testing.T) { testUserAttributeStoreValues(t, ss) })
+	t.Run("DeleteField", func(t *testing.T) { testUserAttributeStoreDeleteField(t, ss) })
+}
+
+func makeUserAttributeFieldForTest(ss store.Store, name string) *model.UserAttributeField {
+	return store.Must(ss.UserAttribute().SaveField(&model.UserAttributeField{
+		Name: name,
+		Type: model.USER_ATTRIBUTE_TYPE_TEXT,
+	})).(*model.UserAttributeField)
+}
+
+func testUserAttributeStoreSaveAndGetField(t *testing.T, ss store.Store) {
+	name := "Department " + model.NewId()
+	field := makeUserAttributeFieldForTest(ss, name)
+	assert.Len(t, field.Id, 26)
+	assert.Equal(t, model.USER_ATTRIBUTE_VISIBILITY_PUBLIC, field.Visibility)
+
+	result := <-ss.UserAttribute().SaveField(&model.UserAttributeField{Name: name, Type: model.USER_ATTRIBUTE_TYPE_TEXT})
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "store.sql_user_attribute.save_field.name_exists.app_error", result.Err.Id)
+
+	result = <-ss.UserAttribute().SaveField(field)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "store.sql_user_attribute.save_field.existing.app_error", result.Err.Id)
+
+	result = <-ss.UserAttribute().GetField(field.Id)
+	require.Nil(t, result.Err)
+	assert.Equal(t, field, result.Data.(*model.UserAttributeField))
+
+	result = <-ss.UserAttribute().GetField(model.NewId())
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+}
+
+func testUserAttributeStoreUpdateField(t *testing.T, ss store.Store) {
+	field := makeUserAttributeFieldForTest(ss, "Office "+model.NewId())
+	other := makeUserAttributeFieldForTest(ss, "Office "+model.NewId())
+
+	field.Type = model.USER_ATTRIBUTE_TYPE_SELECT
+	field.Options = model.StringArray{"Berlin", "Toronto"}
+	field.SortOrder = -1
+	store.Must(ss.UserAttribute().UpdateField(field))
+
+	result := <-ss.UserAttribute().GetFields()
+	require.Nil(t, result.Err)
+	fields := result.Data.([]*model.UserAttributeField)
+	require.NotEmpty(t, fields)
+	assert.Equal(t, field.Id, fields[0].Id, "should be sorted by sort order")
+	assert.Equal(t, model.StringArray{"Berlin", "Toronto"}, fields[0].Options)
+
+	other.Name = field.Name
+	result = <-ss.UserAttribute().UpdateField(other)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "store.sql_user_attribute.save_field.name_exists.app_error", result.Err.Id)
+}
+
+func testUserAttributeStoreValues(t *testing.T, ss store.Store) {
+	field := makeUserAttributeFieldForTest(ss, "Employee ID "+model.NewId())
+	userId1 := model.NewId()
+	userId2 := model.NewId()
+
+	store.Must(ss.UserAttribute().SaveValue(&model.UserAttribute{UserId: userId1, FieldId: field.Id, Value: "1"}))
+	store.Must(ss.UserAttribute().SaveValue(&model.UserAttribute{UserId: userId1, FieldId: field.Id, Value: "2"}))
+	store.Must(ss.UserAttribute().SaveValue(&model.UserAttribute{UserId: userId2, FieldId: field.Id, Value: "3"}))
+
+	result := <-ss.UserAttribute().SaveValue(&model.UserAttribute{UserId: userId1, FieldId: field.Id})
+	require.NotNil(t, result.Err, "shouldn't save an empty value")
+
+	result = <-ss.UserAttribute().GetValuesForUser(userId1)
+	require.Nil(t, result.Err)
+	values := result.Data.([]*model.UserAttribute)
+	require.Len(t, values, 1)
+	assert.Equal(t, "2", values[0].Value)
+
+	result = <-ss.UserAttribute().GetValuesForUsers([]string{userId1, userId2, model.NewId()})
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]*model.UserAttribute), 2)
+
+	result = <-ss.UserAttribute().GetValuesForUsers([]string{})
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]*model.UserAttribute), 0)
+
+	store.Must(ss.UserAttribute().DeleteValue(userId1, field.Id))
+
+	result = <-ss.UserAttribute().GetValuesForField(field.Id)
+	require.Nil(t, result.Err)
+	values = result.Data.([]*model.UserAttribute)
+	require.Len(t, values, 1)
+	assert.Equal(t, userId2, values[0].UserId)
+
+	store.Must(ss.UserAttribute().PermanentDeleteByUser(userId2))
+
+	result = <-ss.UserAttribute().GetValuesForField(field.Id)
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]*model.UserAttribute), 0)
+}
+
+func testUserAttributeStoreDeleteField(t *testing.T, ss store.Store) {
+	field := makeUserAttributeFieldForTest(ss, "Start Date "+model.NewId())
+	userId := model.NewId()
+	store.Must(ss.UserAttribute().SaveValue(&model.UserAttribute{UserId: userId, FieldId: field.Id, Value: "2018-01-02"}))
+
+	store.Must(ss.UserAttribute().DeleteField(field.Id))
+
+	result := <-ss.UserAttribute().GetField(field.Id)
+	require.NotNil(t, result.Err)
+	assert.Equal(t, http.StatusNotFound, result.Err.StatusCode)
+
+	result = <-ss.UserAttribute().GetValuesForUser(userId)
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]*model.UserAttribute), 0)
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/store"
@@ -22,10 +23,12 @@ func TestUserStore(t *testing.T, ss store.Store) {
 	t.Run("Get", func(t *testing.T) { testUserStoreGet(t, ss) })
 	t.Run("UserCount", func(t *testing.T) { testUserCount(t, ss) })
 	t.Run("GetAllUsingAuthService", func(t *testing.T) { testGetAllUsingAuthService(t, ss) })
+	t.Run("GetUsingAuthService", func(t *testing.T) { testGetUsingAuthService(t, ss) })
 	t.Run("GetAllProfiles", func(t *testing.T) { testUserStoreGetAllProfiles(t, ss) })
 	t.Run("GetProfiles", func(t *testing.T) { testUserStoreGetProfiles(t, ss) })
 	t.Run("GetProfilesInChannel", func(t *testing.T) { testUserStoreGetProfilesInChannel(t, ss) })
 	t.Run("GetProfilesInChannelByStatus", func(t *testing.T) { testUserStoreGetProfilesInChannelByStatus(t, ss) })
+	t.Run("GetBotsInChannel", func(t *testing.T) { testUserStoreGetBotsInChannel(t, ss) })
 	t.Run("GetProfilesWithoutTeam", func(t *testing.T) { testUserStoreGetProfilesWithoutTeam(t, ss) })
 	t.Run("GetAllProfilesInChannel", func(t *testing.T) { testUserStoreGetAllProfilesInChannel(t, ss) })
 	t.Run("GetProfilesNotInChannel", func(t *testing.T) { testUserStoreGetProfilesNotInChannel(t, ss) })
@@ -46,9 +49,20 @@ func TestUserStore(t *testing.T, ss store.Store) {
 	t.Run("GetNewUsersForTeam", func(t *testing.T) { testUserStoreGetNewUsersForTeam(t, ss) })
 	t.Run("Search", func(t *testing.T) { testUserStoreSearch(t, ss) })
 	t.Run("SearchWithoutTeam", func(t *testing.T) { testUserStoreSearchWithoutTeam(t, ss) })
+	t.Run("SearchRanking", func(t *testing.T) { testUserStoreSearchRanking(t, ss) })
+	t.Run("SearchExclusions", func(t *testing.T) { testUserStoreSearchExclusions(t, ss) })
 	t.Run("AnalyticsGetInactiveUsersCount", func(t *testing.T) { testUserStoreAnalyticsGetInactiveUsersCount(t, ss) })
 	t.Run("AnalyticsGetSystemAdminCount", func(t *testing.T) { testUserStoreAnalyticsGetSystemAdminCount(t, ss) })
 	t.Run("GetProfilesNotInTeam", func(t *testing.T) { testUserStoreGetProfilesNotInTeam(t, ss) })
+	t.Run("GetProfilesInUserChannels", func(t *testing.T) { testUserStoreGetProfilesInUserChannels(t, ss) })
+	t.Run("SearchInUserChannels", func(t *testing.T) { testUserStoreSearchInUserChannels(t, ss) })
+	t.Run("GetIdsSharingChannelsWithUser", func(t *testing.T) { testUserStoreGetIdsSharingChannelsWithUser(t, ss) })
+	t.Run("PromoteAndDemoteGuest", func(t *testing.T) { testUserStorePromoteAndDemoteGuest(t, ss) })
+	t.Run("ScheduledDeactivation", func(t *testing.T) { testUserStoreScheduledDeactivation(t, ss) })
+	t.Run("UpdateLastSeenAt", func(t *testing.T) { testUserStoreUpdateLastSeenAt(t, ss) })
+	t.Run("GetProfilesWithFilter", func(t *testing.T) { testUserStoreGetProfilesWithFilter(t, ss) })
+	t.Run("GetByFullNames", func(t *testing.T) { testUserStoreGetByFullNames(t, ss) })
+	t.Run("UsernameHistory", func(t *testing.T) { testUserStoreUsernameHistory(t, ss) })
 }
 
 func testUserStoreSave(t *testing.T, ss store.Store) {
@@ -271,6 +285,66 @@ func testGetAllUsingAuthService(t *testing.T, ss store.Store) {
 	}
 }
 
+func testGetUsingAuthService(t *testing.T, ss store.Store) {
+	authService := model.NewId()
+
+	u1 := &model.User{Email: model.NewId(), AuthService: authService, AuthData: model.NewString(model.NewId())}
+	store.Must(ss.User().Save(u1))
+	defer ss.User().PermanentDelete(u1.Id)
+
+	// make sure the users are created in order
+	time.Sleep(time.Millisecond)
+
+	u2 := &model.User{Email: model.NewId(), AuthService: authService, AuthData: model.NewString(model.NewId())}
+	store.Must(ss.User().Save(u2))
+	defer ss.User().PermanentDelete(u2.Id)
+
+	bot := &model.User{Email: model.NewId(), AuthService: authService, AuthData: model.NewString(model.NewId()), IsBot: true}
+	store.Must(ss.User().Save(bot))
+	defer ss.User().PermanentDelete(bot.Id)
+
+	other := &model.User{Email: model.NewId(), AuthService: model.NewId(), AuthData: model.NewString(model.NewId())}
+	store.Must(ss.User().Save(other))
+	defer ss.User().PermanentDelete(other.Id)
+
+	t.Run("all", func(t *testing.T) {
+		result := <-ss.User().GetUsingAuthService(authService, "", 0, 100)
+		require.Nil(t, result.Err)
+
+		users := result.Data.([]*model.User)
+		require.Len(t, users, 2)
+		assert.Equal(t, u1.Id, users[0].Id)
+		assert.Equal(t, u2.Id, users[1].Id)
+		assert.Equal(t, *u1.AuthData, *users[0].AuthData)
+
+		result = <-ss.User().CountUsingAuthService(authService, "")
+		require.Nil(t, result.Err)
+		assert.Equal(t, int64(2), result.Data.(int64))
+	})
+
+	t.Run("paged", func(t *testing.T) {
+		result := <-ss.User().GetUsingAuthService(authService, "", 1, 1)
+		require.Nil(t, result.Err)
+
+		users := result.Data.([]*model.User)
+		require.Len(t, users, 1)
+		assert.Equal(t, u2.Id, users[0].Id)
+	})
+
+	t.Run("by auth data", func(t *testing.T) {
+		result := <-ss.User().GetUsingAuthService(authService, *u2.AuthData, 0, 100)
+		require.Nil(t, result.Err)
+
+		users := result.Data.([]*model.User)
+		require.Len(t, users, 1)
+		assert.Equal(t, u2.Id, users[0].Id)
+
+		result = <-ss.User().CountUsingAuthService(authService, *bot.AuthData)
+		require.Nil(t, result.Err)
+		assert.Equal(t, int64(0), result.Data.(int64))
+	})
+}
+
 func testUserStoreGetAllProfiles(t *testing.T, ss store.Store) {
 	u1 := &model.User{}
 	u1.Email = model.NewId()
@@ -389,6 +463,32 @@ func testUserStoreGetProfiles(t *testing.T, ss store.Store) {
 	}
 }
 
+func testUserStoreGetBotsInChannel(t *testing.T, ss store.Store) {
+	user := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: "user" + model.NewId()})).(*model.User)
+	bot := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: "bot" + model.NewId(), IsBot: true})).(*model.User)
+	deactivatedBot := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: "bot" + model.NewId(), IsBot: true, DeleteAt: model.GetMillis()})).(*model.User)
+	// A bot that isn't a member of the channel
+	store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: "bot" + model.NewId(), IsBot: true}))
+
+	channel := store.Must(ss.Channel().Save(&model.Channel{
+		TeamId:      model.NewId(),
+		DisplayName: "Bots in channel",
+		Name:        "bots-" + model.NewId(),
+		Type:        model.CHANNEL_OPEN,
+	}, -1)).(*model.Channel)
+
+	for _, userId := range []string{user.Id, bot.Id, deactivatedBot.Id} {
+		store.Must(ss.Channel().SaveMember(&model.ChannelMember{ChannelId: channel.Id, UserId: userId, NotifyProps: model.GetDefaultChannelNotifyProps()}))
+	}
+
+	result := <-ss.User().GetBotsInChannel(channel.Id)
+	require.Nil(t, result.Err)
+	bots := result.Data.([]*model.User)
+	require.Len(t, bots, 1)
+	assert.Equal(t, bot.Id, bots[0].Id)
+	assert.Equal(t, "", bots[0].Password)
+}
+
 func testUserStoreGetProfilesInChannel(t *testing.T, ss store.Store) {
 	teamId := model.NewId()
 
@@ -2118,3 +2218,197 @@ func testUserStoreGetProfilesNotInTeam(t *testing.T, ss store.Store) {
 		}
 	}
 }
+
+func testUserStoreScheduledDeactivation(t *testing.T, ss store.Store) {
+	now := model.GetMillis()
+
+	u1 := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: "u1" + model.NewId()})).(*model.User)
+	defer ss.User().PermanentDelete(u1.Id)
+	u2 := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: "u2" + model.NewId()})).(*model.User)
+	defer ss.User().PermanentDelete(u2.Id)
+	u3 := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: "u3" + model.NewId(), DeleteAt: now})).(*model.User)
+	defer ss.User().PermanentDelete(u3.Id)
+
+	require.Nil(t, (<-ss.User().UpdateDeactivateAt(u1.Id, now+1000)).Err)
+	require.Nil(t, (<-ss.User().UpdateDeactivateAt(u2.Id, now+2000)).Err)
+	require.NotNil(t, (<-ss.User().UpdateDeactivateAt(u3.Id, now+1000)).Err, "deactivated users can't be scheduled")
+	require.NotNil(t, (<-ss.User().UpdateDeactivateAt(model.NewId(), now+1000)).Err)
+
+	user := store.Must(ss.User().Get(u1.Id)).(*model.User)
+	assert.Equal(t, now+1000, user.DeactivateAt)
+
+	user.Nickname = "changed"
+	store.Must(ss.User().Update(user, false))
+	user.DeactivateAt = 0
+	store.Must(ss.User().Update(user, false))
+	user = store.Must(ss.User().Get(u1.Id)).(*model.User)
+	assert.Equal(t, now+1000, user.DeactivateAt, "untrusted updates shouldn't change the schedule")
+
+	dueIds := func(before int64) []string {
+		ids := []string{}
+		for _, user := range store.Must(ss.User().GetDueForDeactivation(before)).([]*model.User) {
+			if user.Id == u1.Id || user.Id == u2.Id || user.Id == u3.Id {
+				ids = append(ids, user.Id)
+			}
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{}, dueIds(now+999))
+	assert.Equal(t, []string{u1.Id}, dueIds(now+1000))
+	assert.Equal(t, []string{u1.Id, u2.Id}, dueIds(now+5000))
+
+	require.Nil(t, (<-ss.User().UpdateDeactivateAt(u1.Id, 0)).Err)
+	assert.Equal(t, []string{u2.Id}, dueIds(now+5000))
+}
+
+func testUserStoreUpdateLastSeenAt(t *testing.T, ss store.Store) {
+	u1 := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: "u1" + model.NewId()})).(*model.User)
+	defer ss.User().PermanentDelete(u1.Id)
+	assert.Equal(t, int64(0), u1.LastSeenAt)
+
+	require.Nil(t, (<-ss.User().UpdateLastSeenAt(u1.Id, 2000)).Err)
+	user := store.Must(ss.User().Get(u1.Id)).(*model.User)
+	assert.Equal(t, int64(2000), user.LastSeenAt)
+	assert.Equal(t, u1.UpdateAt, user.UpdateAt, "shouldn't change the user's etag")
+
+	require.Nil(t, (<-ss.User().UpdateLastSeenAt(u1.Id, 1000)).Err)
+	user = store.Must(ss.User().Get(u1.Id)).(*model.User)
+	assert.Equal(t, int64(2000), user.LastSeenAt, "shouldn't go back in time")
+
+	user.LastSeenAt = 0
+	store.Must(ss.User().Update(user, true))
+	user = store.Must(ss.User().Get(u1.Id)).(*model.User)
+	assert.Equal(t, int64(2000), user.LastSeenAt, "updates shouldn't change it")
+}
+
+func testUserStoreGetProfilesWithFilter(t *testing.T, ss store.Store) {
+	teamId := model.NewId()
+
+	u1 := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: "u1" + model.NewId(), Roles: model.SYSTEM_USER_ROLE_ID + " " + model.SYSTEM_ADMIN_ROLE_ID})).(*model.User)
+	defer ss.User().PermanentDelete(u1.Id)
+	time.Sleep(time.Millisecond)
+	u2 := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: "u2" + model.NewId(), AuthService: model.USER_AUTH_SERVICE_LDAP, AuthData: model.NewString(model.NewId())})).(*model.User)
+	defer ss.User().PermanentDelete(u2.Id)
+	time.Sleep(time.Millisecond)
+	u3 := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: "u3" + model.NewId(), AuthService: model.USER_AUTH_SERVICE_LDAP, AuthData: model.NewString(model.NewId()), DeleteAt: model.GetMillis()})).(*model.User)
+	defer ss.User().PermanentDelete(u3.Id)
+	time.Sleep(time.Millisecond)
+	u4 := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: "u4" + model.NewId()})).(*model.User)
+	defer ss.User().PermanentDelete(u4.Id)
+	time.Sleep(time.Millisecond)
+	u5 := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: "u5" + model.NewId()})).(*model.User)
+	defer ss.User().PermanentDelete(u5.Id)
+
+	for _, user := range []*model.User{u1, u2, u3, u4} {
+		store.Must(ss.Team().SaveMember(&model.TeamMember{TeamId: teamId, UserId: user.Id}, -1))
+	}
+
+	store.Must(ss.User().UpdateLastSeenAt(u1.Id, 3000))
+	store.Must(ss.User().UpdateLastSeenAt(u2.Id, 1000))
+	store.Must(ss.User().UpdateLastSeenAt(u3.Id, 2000))
+
+	active := true
+	inactive := false
+
+	for name, tc := range map[string]struct {
+		Filter   model.UserFilter
+		Expected []*model.User
+	}{
+		"team": {
+			Filter:   model.UserFilter{},
+			Expected: []*model.User{u1, u2, u3, u4},
+		},
+		"role": {
+			Filter:   model.UserFilter{Role: model.SYSTEM_ADMIN_ROLE_ID},
+			Expected: []*model.User{u1},
+		},
+		"auth service": {
+			Filter:   model.UserFilter{AuthService: model.USER_AUTH_SERVICE_LDAP},
+			Expected: []*model.User{u2, u3},
+		},
+		"email auth service": {
+			Filter:   model.UserFilter{AuthService: model.USER_AUTH_SERVICE_EMAIL},
+			Expected: []*model.User{u1, u4},
+		},
+		"active": {
+			Filter:   model.UserFilter{Active: &active},
+			Expected: []*model.User{u1, u2, u4},
+		},
+		"inactive": {
+			Filter:   model.UserFilter{Active: &inactive},
+			Expected: []*model.User{u3},
+		},
+		"created": {
+			Filter:   model.UserFilter{CreatedAfter: u2.CreateAt, CreatedBefore: u4.CreateAt},
+			Expected: []*model.User{u2, u3},
+		},
+		"last seen after": {
+			Filter:   model.UserFilter{LastSeenAfter: 2000},
+			Expected: []*model.User{u1, u3},
+		},
+		"last seen before": {
+			Filter:   model.UserFilter{LastSeenBefore: 2000},
+			Expected: []*model.User{u2, u4},
+		},
+		"combined": {
+			Filter:   model.UserFilter{AuthService: model.USER_AUTH_SERVICE_LDAP, Active: &inactive, LastSeenBefore: 2500},
+			Expected: []*model.User{u3},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			filter := tc.Filter
+			filter.TeamId = teamId
+
+			users := store.Must(ss.User().GetProfilesWithFilter(&filter, 0, 100)).([]*model.User)
+			ids := make([]string, len(users))
+			for i, user := range users {
+				ids[i] = user.Id
+			}
+			expectedIds := make([]string, len(tc.Expected))
+			for i, user := range tc.Expected {
+				expectedIds[i] = user.Id
+			}
+			assert.Equal(t, expectedIds, ids)
+
+			count := store.Must(ss.User().CountWithFilter(&filter)).(int64)
+			assert.Equal(t, int64(len(tc.Expected)), count)
+		})
+	}
+
+	filter := &model.UserFilter{TeamId: teamId, Active: &active}
+	users := store.Must(ss.User().GetProfilesWithFilter(filter, 1, 2)).([]*model.User)
+	require.Len(t, users, 2)
+	assert.Equal(t, u2.Id, users[0].Id)
+	assert.Equal(t, u4.Id, users[1].Id)
+	assert.Equal(t, int64(3), store.Must(ss.User().CountWithFilter(filter)).(int64), "should count all matching users, not just the page")
+
+	users = store.Must(ss.User().GetProfilesWithFilter(&model.UserFilter{LastSeenAfter: 2000}, 0, 10000)).([]*model.User)
+	found := false
+	for _, user := range users {
+		found = found || user.Id == u1.Id
+	}
+	assert.True(t, found, "should find users on any team without a team filter")
+}
+
+func testUserStoreGetByFullNames(t *testing.T, ss store.Store) {
+	lastName := "Last" + model.NewId()
+
+	u1 := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: "u1" + model.NewId(), FirstName: "First", LastName: lastName})).(*model.User)
+	defer ss.User().PermanentDelete(u1.Id)
+	u2 := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: "u2" + model.NewId(), FirstName: "first", LastName: lastName, DeleteAt: model.GetMillis()})).(*model.User)
+	defer ss.User().PermanentDelete(u2.Id)
+	u3 := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: "u3" + model.NewId(), FirstName: lastName})).(*model.User)
+	defer ss.User().PermanentDelete(u3.Id)
+
+	users := store.Must(ss.User().GetByFullNames([]string{"FIRST " + lastName})).([]*model.User)
+	require.Len(t, users, 1, "should match case-insensitively and skip deactivated users")
+	assert.Equal(t, u1.Id, users[0].Id)
+	assert.Empty(t, users[0].Password)
+
+	users = store.Must(ss.User().GetByFullNames([]string{lastName, "First " + lastName})).([]*model.User)
+	assert.Len(t, users, 2, "should match users without a last name")
+
+	users = store.Must(ss.User().GetByFullNames([]string{})).([]*model.User)
+	assert.Empty(t, users)
+}
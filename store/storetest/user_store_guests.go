@@ -0,0 +1,140 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package storetest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+type userChannelsFixture struct {
+	TeamId  string
+	Guest   *model.User
+	Member  *model.User
+	Outside *model.User
+	Channel *model.Channel
+	Other   *model.Channel
+}
+
+// saveUserChannelsFixture creates a guest sharing one channel with a member, and a third user who
+// only belongs to a channel the guest isn't in.
+func saveUserChannelsFixture(t *testing.T, ss store.Store) *userChannelsFixture {
+	f := &userChannelsFixture{TeamId: model.NewId()}
+
+	f.Guest = store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: "guest" + model.NewId(), Roles: model.SYSTEM_GUEST_ROLE_ID})).(*model.User)
+	f.Member = store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: "member" + model.NewId()})).(*model.User)
+	f.Outside = store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: "outside" + model.NewId()})).(*model.User)
+
+	f.Channel = store.Must(ss.Channel().Save(&model.Channel{TeamId: f.TeamId, DisplayName: "Guests", Name: "zz" + model.NewId() + "b", Type: model.CHANNEL_PRIVATE}, -1)).(*model.Channel)
+	f.Other = store.Must(ss.Channel().Save(&model.Channel{TeamId: f.TeamId, DisplayName: "Members", Name: "zz" + model.NewId() + "b", Type: model.CHANNEL_OPEN}, -1)).(*model.Channel)
+
+	for _, member := range []*model.ChannelMember{
+		{ChannelId: f.Channel.Id, UserId: f.Guest.Id, Roles: model.CHANNEL_GUEST_ROLE_ID},
+		{ChannelId: f.Channel.Id, UserId: f.Member.Id, Roles: model.CHANNEL_USER_ROLE_ID},
+		{ChannelId: f.Other.Id, UserId: f.Member.Id, Roles: model.CHANNEL_USER_ROLE_ID},
+		{ChannelId: f.Other.Id, UserId: f.Outside.Id, Roles: model.CHANNEL_USER_ROLE_ID},
+	} {
+		member.NotifyProps = model.GetDefaultChannelNotifyProps()
+		store.Must(ss.Channel().SaveMember(member))
+	}
+
+	return f
+}
+
+func userIdsForTest(users []*model.User) []string {
+	ids := make([]string, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.Id)
+	}
+	return ids
+}
+
+func testUserStoreGetProfilesInUserChannels(t *testing.T, ss store.Store) {
+	f := saveUserChannelsFixture(t, ss)
+
+	result := <-ss.User().GetProfilesInUserChannels(f.Guest.Id, f.TeamId, 0, 100)
+	require.Nil(t, result.Err)
+	ids := userIdsForTest(result.Data.([]*model.User))
+	assert.ElementsMatch(t, []string{f.Guest.Id, f.Member.Id}, ids)
+
+	result = <-ss.User().GetProfilesInUserChannels(f.Guest.Id, "", 0, 100)
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]*model.User), 2)
+
+	result = <-ss.User().GetProfilesInUserChannels(f.Guest.Id, model.NewId(), 0, 100)
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]*model.User), 0)
+
+	result = <-ss.User().GetProfilesInUserChannels(f.Member.Id, f.TeamId, 0, 100)
+	require.Nil(t, result.Err)
+	ids = userIdsForTest(result.Data.([]*model.User))
+	assert.ElementsMatch(t, []string{f.Guest.Id, f.Member.Id, f.Outside.Id}, ids)
+
+	result = <-ss.User().GetProfilesInUserChannels(f.Member.Id, f.TeamId, 0, 1)
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]*model.User), 1)
+}
+
+func testUserStoreSearchInUserChannels(t *testing.T, ss store.Store) {
+	f := saveUserChannelsFixture(t, ss)
+
+	result := <-ss.User().SearchInUserChannels(f.Guest.Id, f.TeamId, "member", map[string]bool{})
+	require.Nil(t, result.Err)
+	assert.Equal(t, []string{f.Member.Id}, userIdsForTest(result.Data.([]*model.User)))
+
+	result = <-ss.User().SearchInUserChannels(f.Guest.Id, f.TeamId, "outside", map[string]bool{})
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]*model.User), 0)
+
+	result = <-ss.User().SearchInUserChannels(f.Member.Id, "", "outside", map[string]bool{})
+	require.Nil(t, result.Err)
+	assert.Equal(t, []string{f.Outside.Id}, userIdsForTest(result.Data.([]*model.User)))
+}
+
+func testUserStoreGetIdsSharingChannelsWithUser(t *testing.T, ss store.Store) {
+	f := saveUserChannelsFixture(t, ss)
+
+	result := <-ss.User().GetIdsSharingChannelsWithUser(f.Guest.Id, []string{f.Member.Id, f.Outside.Id, model.NewId()})
+	require.Nil(t, result.Err)
+	assert.Equal(t, []string{f.Member.Id}, result.Data.([]string))
+
+	result = <-ss.User().GetIdsSharingChannelsWithUser(f.Guest.Id, []string{})
+	require.Nil(t, result.Err)
+	assert.Len(t, result.Data.([]string), 0)
+}
+
+func testUserStorePromoteAndDemoteGuest(t *testing.T, ss store.Store) {
+	f := saveUserChannelsFixture(t, ss)
+	store.Must(ss.Team().SaveMember(&model.TeamMember{TeamId: f.TeamId, UserId: f.Guest.Id, Roles: model.TEAM_GUEST_ROLE_ID}, -1))
+
+	require.Nil(t, (<-ss.User().PromoteGuestToUser(f.Guest.Id)).Err)
+
+	user := store.Must(ss.User().Get(f.Guest.Id)).(*model.User)
+	assert.Equal(t, model.SYSTEM_USER_ROLE_ID, user.Roles)
+	assert.True(t, user.UpdateAt >= f.Guest.UpdateAt)
+	teamMember := store.Must(ss.Team().GetMember(f.TeamId, f.Guest.Id)).(*model.TeamMember)
+	assert.Equal(t, model.TEAM_USER_ROLE_ID, teamMember.Roles)
+	channelMember := store.Must(ss.Channel().GetMember(f.Channel.Id, f.Guest.Id)).(*model.ChannelMember)
+	assert.Equal(t, model.CHANNEL_USER_ROLE_ID, channelMember.Roles)
+
+	// Other users are left alone
+	channelMember = store.Must(ss.Channel().GetMember(f.Channel.Id, f.Member.Id)).(*model.ChannelMember)
+	assert.Equal(t, model.CHANNEL_USER_ROLE_ID, channelMember.Roles)
+
+	store.Must(ss.Channel().UpdateMember(&model.ChannelMember{ChannelId: f.Channel.Id, UserId: f.Guest.Id, Roles: model.CHANNEL_USER_ROLE_ID + " " + model.CHANNEL_ADMIN_ROLE_ID, NotifyProps: model.GetDefaultChannelNotifyProps()}))
+
+	require.Nil(t, (<-ss.User().DemoteUserToGuest(f.Guest.Id)).Err)
+
+	user = store.Must(ss.User().Get(f.Guest.Id)).(*model.User)
+	assert.Equal(t, model.SYSTEM_GUEST_ROLE_ID, user.Roles)
+	teamMember = store.Must(ss.Team().GetMember(f.TeamId, f.Guest.Id)).(*model.TeamMember)
+	assert.Equal(t, model.TEAM_GUEST_ROLE_ID, teamMember.Roles)
+	channelMember = store.Must(ss.Channel().GetMember(f.Channel.Id, f.Guest.Id)).(*model.ChannelMember)
+	assert.Equal(t, model.CHANNEL_GUEST_ROLE_ID, channelMember.Roles)
+}
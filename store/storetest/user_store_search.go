@@ -0,0 +1,104 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package storetest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func searchResultIds(t *testing.T, result store.StoreResult) []string {
+	require.Nil(t, result.Err)
+
+	ids := []string{}
+	for _, user := range result.Data.([]*model.User) {
+		ids = append(ids, user.Id)
+	}
+
+	return ids
+}
+
+func testUserStoreSearchRanking(t *testing.T, ss store.Store) {
+	term := "rank" + model.NewId()[:10]
+
+	exact := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: term})).(*model.User)
+	longer := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: term + "ab"})).(*model.User)
+	nickname := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: "aaa" + model.NewId(), Nickname: term + "abnick"})).(*model.User)
+
+	teamId := model.NewId()
+	channel := store.Must(ss.Channel().Save(&model.Channel{TeamId: teamId, DisplayName: "Ranking", Name: "zz" + model.NewId() + "b", Type: model.CHANNEL_OPEN}, -1)).(*model.Channel)
+
+	for _, user := range []*model.User{exact, longer, nickname} {
+		store.Must(ss.Team().SaveMember(&model.TeamMember{TeamId: teamId, UserId: user.Id}, -1))
+		store.Must(ss.Channel().SaveMember(&model.ChannelMember{ChannelId: channel.Id, UserId: user.Id, NotifyProps: model.GetDefaultChannelNotifyProps()}))
+	}
+
+	searchOptions := map[string]bool{store.USER_SEARCH_OPTION_NAMES_ONLY: true}
+	expected := []string{exact.Id, nickname.Id, longer.Id}
+
+	t.Run("exact username first", func(t *testing.T) {
+		assert.Equal(t, expected, searchResultIds(t, <-ss.User().Search(teamId, term, searchOptions)))
+		assert.Equal(t, expected, searchResultIds(t, <-ss.User().Search("", term, searchOptions)))
+		assert.Equal(t, expected, searchResultIds(t, <-ss.User().SearchInChannel(channel.Id, term, searchOptions)))
+	})
+
+	t.Run("case is ignored", func(t *testing.T) {
+		assert.Equal(t, expected, searchResultIds(t, <-ss.User().Search(teamId, strings.ToUpper(term), searchOptions)))
+	})
+
+	t.Run("otherwise ordered by username", func(t *testing.T) {
+		assert.Equal(t, []string{nickname.Id, longer.Id}, searchResultIds(t, <-ss.User().Search(teamId, term+"a", searchOptions)))
+	})
+}
+
+func testUserStoreSearchExclusions(t *testing.T, ss store.Store) {
+	term := "excl" + model.NewId()[:10]
+
+	active := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: term + "active"})).(*model.User)
+	bot := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: term + "bot", IsBot: true})).(*model.User)
+	deactivated := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: term + "deactivated", DeleteAt: model.GetMillis()})).(*model.User)
+
+	teamId := model.NewId()
+	channel := store.Must(ss.Channel().Save(&model.Channel{TeamId: teamId, DisplayName: "Exclusions", Name: "zz" + model.NewId() + "b", Type: model.CHANNEL_OPEN}, -1)).(*model.Channel)
+	other := store.Must(ss.Channel().Save(&model.Channel{TeamId: teamId, DisplayName: "Other", Name: "zz" + model.NewId() + "b", Type: model.CHANNEL_OPEN}, -1)).(*model.Channel)
+
+	for _, user := range []*model.User{active, bot, deactivated} {
+		store.Must(ss.Team().SaveMember(&model.TeamMember{TeamId: teamId, UserId: user.Id}, -1))
+		store.Must(ss.Channel().SaveMember(&model.ChannelMember{ChannelId: channel.Id, UserId: user.Id, NotifyProps: model.GetDefaultChannelNotifyProps()}))
+	}
+
+	for name, tc := range map[string]struct {
+		Options  map[string]bool
+		Expected []string
+	}{
+		"deactivated users are excluded by default": {
+			Options:  map[string]bool{},
+			Expected: []string{active.Id, bot.Id},
+		},
+		"deactivated users are included when allowed": {
+			Options:  map[string]bool{store.USER_SEARCH_OPTION_ALLOW_INACTIVE: true},
+			Expected: []string{active.Id, bot.Id, deactivated.Id},
+		},
+		"bots are excluded": {
+			Options:  map[string]bool{store.USER_SEARCH_OPTION_EXCLUDE_BOTS: true},
+			Expected: []string{active.Id},
+		},
+		"bots are excluded while deactivated users are allowed": {
+			Options:  map[string]bool{store.USER_SEARCH_OPTION_ALLOW_INACTIVE: true, store.USER_SEARCH_OPTION_EXCLUDE_BOTS: true},
+			Expected: []string{active.Id, deactivated.Id},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.Expected, searchResultIds(t, <-ss.User().Search(teamId, term, tc.Options)), "in team")
+			assert.Equal(t, tc.Expected, searchResultIds(t, <-ss.User().SearchInChannel(channel.Id, term, tc.Options)), "in channel")
+			assert.Equal(t, tc.Expected, searchResultIds(t, <-ss.User().SearchNotInChannel(teamId, other.Id, term, tc.Options)), "not in channel")
+		})
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package storetest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func testUserStoreUsernameHistory(t *testing.T, ss store.Store) {
+	user := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: "u" + model.NewId()})).(*model.User)
+	defer ss.User().PermanentDelete(user.Id)
+	other := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: "u" + model.NewId()})).(*model.User)
+	defer ss.User().PermanentDelete(other.Id)
+
+	oldUsername := "u" + model.NewId()
+	result := <-ss.User().SaveUsernameHistory(&model.UsernameHistory{Username: oldUsername, UserId: user.Id, CreateAt: 1000}, 2)
+	require.Nil(t, result.Err)
+
+	t.Run("get by username", func(t *testing.T) {
+		result := <-ss.User().GetUsernameHistoryByUsername(oldUsername)
+		require.Nil(t, result.Err)
+		assert.Equal(t, user.Id, result.Data.(*model.UsernameHistory).UserId)
+
+		result = <-ss.User().GetUsernameHistoryByUsername("u" + model.NewId())
+		require.NotNil(t, result.Err)
+		assert.Equal(t, "store.sql_user.username_history.get_by_username.missing.app_error", result.Err.Id)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		result := <-ss.User().SaveUsernameHistory(&model.UsernameHistory{Username: "", UserId: user.Id}, 2)
+		require.NotNil(t, result.Err)
+	})
+
+	t.Run("taken over by another user", func(t *testing.T) {
+		sharedUsername := "u" + model.NewId()
+		store.Must(ss.User().SaveUsernameHistory(&model.UsernameHistory{Username: sharedUsername, UserId: other.Id, CreateAt: 1000}, 2))
+		store.Must(ss.User().SaveUsernameHistory(&model.UsernameHistory{Username: sharedUsername, UserId: user.Id, CreateAt: 2000}, 2))
+
+		result := <-ss.User().GetUsernameHistoryByUsername(sharedUsername)
+		require.Nil(t, result.Err)
+		assert.Equal(t, user.Id, result.Data.(*model.UsernameHistory).UserId)
+	})
+
+	t.Run("capped per user", func(t *testing.T) {
+		newestUsername := "u" + model.NewId()
+		store.Must(ss.User().SaveUsernameHistory(&model.UsernameHistory{Username: newestUsername, UserId: user.Id, CreateAt: 3000}, 2))
+
+		result := <-ss.User().GetUsernameHistoryByUsername(oldUsername)
+		require.NotNil(t, result.Err, "the oldest username should have been forgotten")
+
+		result = <-ss.User().GetUsernameHistoryByUsername(newestUsername)
+		require.Nil(t, result.Err)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		userUsername := "u" + model.NewId()
+		store.Must(ss.User().SaveUsernameHistory(&model.UsernameHistory{Username: userUsername, UserId: user.Id}, 2))
+		otherUsername := "u" + model.NewId()
+		store.Must(ss.User().SaveUsernameHistory(&model.UsernameHistory{Username: otherUsername, UserId: other.Id}, 2))
+
+		require.Nil(t, (<-ss.User().DeleteUsernameHistory(user.Id)).Err)
+
+		result := <-ss.User().GetUsernameHistoryByUsername(userUsername)
+		require.NotNil(t, result.Err)
+
+		result = <-ss.User().GetUsernameHistoryByUsername(otherUsername)
+		require.Nil(t, result.Err, "the history of other users should be kept")
+	})
+
+	t.Run("permanently deleted user", func(t *testing.T) {
+		deleted := store.Must(ss.User().Save(&model.User{Email: model.NewId(), Username: "u" + model.NewId()})).(*model.User)
+		deletedUsername := "u" + model.NewId()
+		store.Must(ss.User().SaveUsernameHistory(&model.UsernameHistory{Username: deletedUsername, UserId: deleted.Id}, 2))
+
+		require.Nil(t, (<-ss.User().PermanentDelete(deleted.Id)).Err)
+
+		result := <-ss.User().GetUsernameHistoryByUsername(deletedUsername)
+		require.NotNil(t, result.Err)
+	})
+}
@@ -30,6 +30,7 @@ func TestWebhookStore(t *testing.T, ss store.Store) {
 	t.Run("DeleteOutgoing", func(t *testing.T) { testWebhookStoreDeleteOutgoing(t, ss) })
 	t.Run("DeleteOutgoingByChannel", func(t *testing.T) { testWebhookStoreDeleteOutgoingByChannel(t, ss) })
 	t.Run("DeleteOutgoingByUser", func(t *testing.T) { testWebhookStoreDeleteOutgoingByUser(t, ss) })
+	t.Run("DeleteByTeam", func(t *testing.T) { testWebhookStoreDeleteByTeam(t, ss) })
 	t.Run("UpdateOutgoing", func(t *testing.T) { testWebhookStoreUpdateOutgoing(t, ss) })
 	t.Run("CountIncoming", func(t *testing.T) { testWebhookStoreCountIncoming(t, ss) })
 	t.Run("CountOutgoing", func(t *testing.T) { testWebhookStoreCountOutgoing(t, ss) })
@@ -428,6 +429,38 @@ func testWebhookStoreDeleteOutgoingByChannel(t *testing.T, ss store.Store) {
 	}
 }
 
+func testWebhookStoreDeleteByTeam(t *testing.T, ss store.Store) {
+	incoming := (<-ss.Webhook().SaveIncoming(buildIncomingWebhook())).Data.(*model.IncomingWebhook)
+	otherIncoming := (<-ss.Webhook().SaveIncoming(buildIncomingWebhook())).Data.(*model.IncomingWebhook)
+
+	outgoing := (<-ss.Webhook().SaveOutgoing(&model.OutgoingWebhook{
+		TeamId:       incoming.TeamId,
+		CreatorId:    model.NewId(),
+		TriggerWords: []string{"trigger"},
+		CallbackURLs: []string{"http://nowhere.com/"},
+	})).Data.(*model.OutgoingWebhook)
+
+	if r1 := <-ss.Webhook().PermanentDeleteIncomingByTeam(incoming.TeamId); r1.Err != nil {
+		t.Fatal(r1.Err)
+	}
+
+	if r2 := <-ss.Webhook().PermanentDeleteOutgoingByTeam(outgoing.TeamId); r2.Err != nil {
+		t.Fatal(r2.Err)
+	}
+
+	if r3 := <-ss.Webhook().GetIncoming(incoming.Id, false); r3.Err == nil {
+		t.Fatal("the team's incoming webhook should have been deleted")
+	}
+
+	if r4 := <-ss.Webhook().GetOutgoing(outgoing.Id); r4.Err == nil {
+		t.Fatal("the team's outgoing webhook should have been deleted")
+	}
+
+	if r5 := <-ss.Webhook().GetIncoming(otherIncoming.Id, false); r5.Err != nil {
+		t.Fatal("other teams' webhooks should be left alone")
+	}
+}
+
 func testWebhookStoreDeleteOutgoingByUser(t *testing.T, ss store.Store) {
 	o1 := &model.OutgoingWebhook{}
 	o1.ChannelId = model.NewId()
@@ -0,0 +1,130 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package storetest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/store"
+)
+
+func TestWebSocketOutboxStore(t *testing.T, ss store.Store) {
+	t.Run("SavePostWithWebSocketEvent", func(t *testing.T) { testWebSocketOutboxStoreSavePost(t, ss) })
+	t.Run("SaveReactionWithWebSocketEvent", func(t *testing.T) { testWebSocketOutboxStoreSaveReaction(t, ss) })
+	t.Run("UpdateChannelWithWebSocketEvent", func(t *testing.T) { testWebSocketOutboxStoreUpdateChannel(t, ss) })
+	t.Run("MarkSent", func(t *testing.T) { testWebSocketOutboxStoreMarkSent(t, ss) })
+	t.Run("PermanentDeleteBatch", func(t *testing.T) { testWebSocketOutboxStorePermanentDeleteBatch(t, ss) })
+}
+
+// getUnsentWebSocketEvent returns the unsent outbox event with the given id, or nil if there isn't one.
+func getUnsentWebSocketEvent(t *testing.T, ss store.Store, id string) *model.WebSocketOutboxEvent {
+	events := store.Must(ss.WebSocketOutbox().GetUnsent(0, model.GetMillis()+1, 10000)).([]*model.WebSocketOutboxEvent)
+	for _, event := range events {
+		if event.Id == id {
+			return event
+		}
+	}
+
+	return nil
+}
+
+func testWebSocketOutboxStoreSavePost(t *testing.T, ss store.Store) {
+	post := store.Must(ss.Post().SaveWithWebSocketEvent(&model.Post{ChannelId: model.NewId(), UserId: model.NewId(), Message: "message"})).(*model.Post)
+
+	event := getUnsentWebSocketEvent(t, ss, post.Id)
+	require.NotNil(t, event, "the posted event should be saved with the post")
+	assert.Equal(t, model.WEBSOCKET_EVENT_POSTED, event.Event)
+	assert.Equal(t, post.Id, event.PostId)
+	assert.Zero(t, event.SentAt)
+
+	store.Must(ss.Post().Save(&model.Post{ChannelId: model.NewId(), UserId: model.NewId(), Message: "message"}))
+}
+
+func testWebSocketOutboxStoreSaveReaction(t *testing.T, ss store.Store) {
+	post := store.Must(ss.Post().Save(&model.Post{ChannelId: model.NewId(), UserId: model.NewId(), Message: "message"})).(*model.Post)
+	reaction := &model.Reaction{PostId: post.Id, UserId: model.NewId(), EmojiName: "smile"}
+
+	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_REACTION_ADDED, "", post.ChannelId, "", nil)
+	message.Add("reaction", reaction.ToJson())
+	added := model.NewWebSocketOutboxEvent(message)
+	store.Must(ss.Reaction().SaveWithWebSocketEvent(reaction, added))
+
+	event := getUnsentWebSocketEvent(t, ss, added.Id)
+	require.NotNil(t, event, "the event should be saved with the reaction")
+	assert.Equal(t, model.WEBSOCKET_EVENT_REACTION_ADDED, event.WebSocketEvent().Event)
+	assert.Equal(t, post.ChannelId, event.WebSocketEvent().Broadcast.ChannelId)
+
+	message = model.NewWebSocketEvent(model.WEBSOCKET_EVENT_REACTION_REMOVED, "", post.ChannelId, "", nil)
+	message.Add("reaction", reaction.ToJson())
+	removed := model.NewWebSocketOutboxEvent(message)
+	store.Must(ss.Reaction().DeleteWithWebSocketEvent(reaction, removed))
+
+	event = getUnsentWebSocketEvent(t, ss, removed.Id)
+	require.NotNil(t, event, "the event should be saved with the deletion")
+	assert.Equal(t, model.WEBSOCKET_EVENT_REACTION_REMOVED, event.Event)
+	assert.Empty(t, store.Must(ss.Reaction().GetForPost(post.Id, false)).([]*model.Reaction))
+
+	// The reaction isn't saved if its event can't be
+	invalid := model.NewWebSocketOutboxEvent(&model.WebSocketEvent{})
+	result := <-ss.Reaction().SaveWithWebSocketEvent(&model.Reaction{PostId: post.Id, UserId: model.NewId(), EmojiName: "smile"}, invalid)
+	require.NotNil(t, result.Err)
+	assert.Empty(t, store.Must(ss.Reaction().GetForPost(post.Id, false)).([]*model.Reaction))
+}
+
+func testWebSocketOutboxStoreUpdateChannel(t *testing.T, ss store.Store) {
+	channel := store.Must(ss.Channel().Save(&model.Channel{
+		TeamId:      model.NewId(),
+		DisplayName: "Display Name",
+		Name:        "z-z-z" + model.NewId() + "b",
+		Type:        model.CHANNEL_OPEN,
+	}, -1)).(*model.Channel)
+
+	channel.Header = "new header"
+	message := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_CHANNEL_UPDATED, "", channel.Id, "", nil)
+	updated := model.NewWebSocketOutboxEvent(message)
+	store.Must(ss.Channel().UpdateWithWebSocketEvent(channel, updated))
+
+	require.NotNil(t, getUnsentWebSocketEvent(t, ss, updated.Id), "the event should be saved with the update")
+	assert.Equal(t, "new header", store.Must(ss.Channel().Get(channel.Id, false)).(*model.Channel).Header)
+
+	// The channel isn't updated if its event can't be
+	channel.Header = "newer header"
+	result := <-ss.Channel().UpdateWithWebSocketEvent(channel, model.NewWebSocketOutboxEvent(&model.WebSocketEvent{}))
+	require.NotNil(t, result.Err)
+	assert.Equal(t, "new header", store.Must(ss.Channel().Get(channel.Id, false)).(*model.Channel).Header)
+}
+
+func testWebSocketOutboxStoreMarkSent(t *testing.T, ss store.Store) {
+	post := store.Must(ss.Post().SaveWithWebSocketEvent(&model.Post{ChannelId: model.NewId(), UserId: model.NewId(), Message: "message"})).(*model.Post)
+	require.NotNil(t, getUnsentWebSocketEvent(t, ss, post.Id))
+
+	require.Nil(t, (<-ss.WebSocketOutbox().MarkSent(post.Id, model.GetMillis())).Err)
+	assert.Nil(t, getUnsentWebSocketEvent(t, ss, post.Id))
+
+	require.Nil(t, (<-ss.WebSocketOutbox().MarkSent(model.NewId(), model.GetMillis())).Err, "marking an unknown event should do nothing")
+
+	// Only events created in the given times are returned
+	other := store.Must(ss.Post().SaveWithWebSocketEvent(&model.Post{ChannelId: model.NewId(), UserId: model.NewId(), Message: "message"})).(*model.Post)
+	assert.Empty(t, store.Must(ss.WebSocketOutbox().GetUnsent(other.CreateAt+1, model.GetMillis()+1, 10000)).([]*model.WebSocketOutboxEvent))
+	events := store.Must(ss.WebSocketOutbox().GetUnsent(other.CreateAt, other.CreateAt+1, 10000)).([]*model.WebSocketOutboxEvent)
+	require.NotEmpty(t, events)
+	for _, event := range events {
+		assert.Equal(t, other.CreateAt, event.CreateAt)
+	}
+}
+
+func testWebSocketOutboxStorePermanentDeleteBatch(t *testing.T, ss store.Store) {
+	post := store.Must(ss.Post().SaveWithWebSocketEvent(&model.Post{ChannelId: model.NewId(), UserId: model.NewId(), Message: "message"})).(*model.Post)
+	require.NotNil(t, getUnsentWebSocketEvent(t, ss, post.Id))
+
+	store.Must(ss.WebSocketOutbox().PermanentDeleteBatch(post.CreateAt, 10000))
+	require.NotNil(t, getUnsentWebSocketEvent(t, ss, post.Id), "events created at the end time should be kept")
+
+	store.Must(ss.WebSocketOutbox().PermanentDeleteBatch(model.GetMillis()+1, 10000))
+	assert.Nil(t, getUnsentWebSocketEvent(t, ss, post.Id))
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package teamdeletion
+
+import (
+	"context"
+
+	"github.com/mattermost/mattermost-server/app"
+	"github.com/mattermost/mattermost-server/jobs"
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+type Worker struct {
+	name      string
+	stop      chan bool
+	stopped   chan bool
+	jobs      chan model.Job
+	jobServer *jobs.JobServer
+	app       *app.App
+}
+
+func (m *TeamDeletionJobInterfaceImpl) MakeWorker() model.Worker {
+	worker := Worker{
+		name:      "TeamDeletion",
+		stop:      make(chan bool, 1),
+		stopped:   make(chan bool, 1),
+		jobs:      make(chan model.Job),
+		jobServer: m.App.Jobs,
+		app:       m.App,
+	}
+
+	return &worker
+}
+
+func (worker *Worker) Run() {
+	mlog.Debug("Worker started", mlog.String("worker", worker.name))
+
+	defer func() {
+		mlog.Debug("Worker finished", mlog.String("worker", worker.name))
+		worker.stopped <- true
+	}()
+
+	for {
+		select {
+		case <-worker.stop:
+			mlog.Debug("Worker received stop signal", mlog.String("worker", worker.name))
+			return
+		case job := <-worker.jobs:
+			mlog.Debug("Worker received a new candidate job.", mlog.String("worker", worker.name))
+			worker.DoJob(&job)
+		}
+	}
+}
+
+func (worker *Worker) Stop() {
+	mlog.Debug("Worker stopping", mlog.String("worker", worker.name))
+	worker.stop <- true
+	<-worker.stopped
+}
+
+func (worker *Worker) JobChannel() chan<- model.Job {
+	return worker.jobs
+}
+
+func (worker *Worker) DoJob(job *model.Job) {
+	if claimed, err := worker.jobServer.ClaimJob(job); err != nil {
+		mlog.Info("Worker experienced an error while trying to claim job",
+			mlog.String("worker", worker.name),
+			mlog.String("job_id", job.Id),
+			mlog.String("error", err.Error()))
+		return
+	} else if !claimed {
+		return
+	}
+
+	cancelCtx, cancelCancelWatcher := context.WithCancel(context.Background())
+	cancelWatcherChan := make(chan interface{}, 1)
+	go worker.jobServer.CancellationWatcher(cancelCtx, job.Id, cancelWatcherChan)
+
+	defer cancelCancelWatcher()
+
+	// The job starts from the checkpoint in its data, which a job that resumes an interrupted deletion is made with
+	progress := model.TeamDeletionProgressFromJobData(job.Data)
+
+	canceled := false
+	stopped := false
+	err := worker.app.PermanentDeleteTeamWithProgress(progress, func(progress *model.TeamDeletionProgress) bool {
+		select {
+		case <-cancelWatcherChan:
+			canceled = true
+			return false
+		case <-worker.stop:
+			// The deletion is left to be resumed, and the worker is stopped once the job has been marked
+			stopped = true
+			return false
+		default:
+		}
+
+		setJobData(job, progress)
+		if err := worker.jobServer.SetJobProgress(job, progress.Percent()); err != nil {
+			mlog.Error("Worker: Failed to set progress for job", mlog.String("worker", worker.name), mlog.String("job_id", job.Id), mlog.String("error", err.Error()))
+		}
+
+		return true
+	})
+	setJobData(job, progress)
+
+	if stopped {
+		worker.stop <- true
+	}
+
+	if err != nil {
+		mlog.Error("Worker: Failed to permanently delete the team", mlog.String("worker", worker.name), mlog.String("job_id", job.Id), mlog.String("team_id", progress.TeamId), mlog.String("stage", progress.Stage), mlog.String("error", err.Error()))
+		worker.setJobError(job, err)
+		return
+	}
+
+	if canceled || stopped {
+		mlog.Info("Worker: Job has been canceled", mlog.String("worker", worker.name), mlog.String("job_id", job.Id), mlog.String("stage", progress.Stage))
+		worker.setJobCanceled(job)
+		return
+	}
+
+	mlog.Info("Worker: Job is complete", mlog.String("worker", worker.name), mlog.String("job_id", job.Id), mlog.String("team_id", progress.TeamId))
+	worker.setJobSuccess(job)
+}
+
+func setJobData(job *model.Job, progress *model.TeamDeletionProgress) {
+	if job.Data == nil {
+		job.Data = make(map[string]string)
+	}
+	progress.ToJobData(job.Data)
+}
+
+func (worker *Worker) setJobSuccess(job *model.Job) {
+	if err := worker.jobServer.SetJobProgress(job, 100); err != nil {
+		mlog.Error("Worker: Failed to update progress for job", mlog.String("worker", worker.name), mlog.String("job_id", job.Id), mlog.String("error", err.Error()))
+	}
+
+	if err := worker.jobServer.SetJobSuccess(job); err != nil {
+		mlog.Error("Worker: Failed to set success for job", mlog.String("worker", worker.name), mlog.String("job_id", job.Id), mlog.String("error", err.Error()))
+		worker.setJobError(job, err)
+	}
+}
+
+func (worker *Worker) setJobError(job *model.Job, appError *model.AppError) {
+	if err := worker.jobServer.SetJobError(job, appError); err != nil {
+		mlog.Error("Worker: Failed to set job error", mlog.String("worker", worker.name), mlog.String("job_id", job.Id), mlog.String("error", err.Error()))
+	}
+}
+
+func (worker *Worker) setJobCanceled(job *model.Job) {
+	if err := worker.jobServer.SetJobCanceled(job); err != nil {
+		mlog.Error("Worker: Failed to mark job as canceled", mlog.String("worker", worker.name), mlog.String("job_id", job.Id), mlog.String("error", err.Error()))
+	}
+}
@@ -22,13 +22,36 @@ func CheckOrigin(r *http.Request, allowedOrigins string) bool {
 		return true
 	}
 	for _, allowed := range strings.Split(allowedOrigins, " ") {
-		if allowed == origin {
+		if MatchOrigin(origin, allowed) {
 			return true
 		}
 	}
 	return false
 }
 
+// MatchOrigin returns whether the origin matches a single allowed origin. The allowed origin can start its host
+// with a wildcard such as https://*.example.com to match any subdomain of it, but not example.com itself.
+func MatchOrigin(origin, allowed string) bool {
+	if allowed == origin {
+		return origin != ""
+	}
+
+	schemeEnd := strings.Index(allowed, "://*.")
+	if schemeEnd == -1 {
+		return false
+	}
+
+	prefix := allowed[:schemeEnd+len("://")]
+	suffix := allowed[schemeEnd+len("://*"):]
+	if !strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) || len(origin) <= len(prefix)+len(suffix) {
+		return false
+	}
+
+	// the wildcard only stands in for subdomains, so it can't change the port or include anything else
+	subdomain := origin[len(prefix) : len(origin)-len(suffix)]
+	return !strings.ContainsAny(subdomain, ":/@?#*")
+}
+
 func OriginChecker(allowedOrigins string) func(*http.Request) bool {
 	return func(r *http.Request) bool {
 		return CheckOrigin(r, allowedOrigins)
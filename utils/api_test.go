@@ -47,3 +47,33 @@ func TestRenderWebError(t *testing.T) {
 	h := sha256.Sum256([]byte("/error?foo=bar"))
 	assert.True(t, ecdsa.Verify(&key.PublicKey, h[:], rs.R, rs.S))
 }
+
+func TestCheckOrigin(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Origin         string
+		AllowedOrigins string
+		Expected       bool
+	}{
+		"any origin":                        {"https://example.com", "*", true},
+		"listed origin":                     {"https://example.com", "https://other.com https://example.com", true},
+		"unlisted origin":                   {"https://example.com", "https://other.com", false},
+		"different scheme":                  {"http://example.com", "https://example.com", false},
+		"no origin":                         {"", "https://example.com  https://other.com", false},
+		"wildcard subdomain":                {"https://chat.example.com", "https://*.example.com", true},
+		"wildcard nested subdomain":         {"https://a.chat.example.com", "https://*.example.com", true},
+		"wildcard doesn't match the domain": {"https://example.com", "https://*.example.com", false},
+		"wildcard with a different suffix":  {"https://chat.example.com.evil.com", "https://*.example.com", false},
+		"wildcard with a lookalike domain":  {"https://chatexample.com", "https://*.example.com", false},
+		"wildcard with a different scheme":  {"http://chat.example.com", "https://*.example.com", false},
+		"wildcard with the same port":       {"https://chat.example.com:8443", "https://*.example.com:8443", true},
+		"wildcard with a different port":    {"https://chat.example.com:9000", "https://*.example.com:8443", false},
+		"wildcard can't add a port":         {"https://evil.com:1.example.com", "https://*.example.com", false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "http://foo", nil)
+			r.Header.Set("Origin", tc.Origin)
+
+			assert.Equal(t, tc.Expected, CheckOrigin(r, tc.AllowedOrigins))
+		})
+	}
+}
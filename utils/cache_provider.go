@@ -0,0 +1,123 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package utils
+
+import (
+	"sync"
+
+	"github.com/mattermost/mattermost-server/einterfaces"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+// CACHE_PURGE_KEY is sent in place of a key to clear a whole cache across the cluster.
+const CACHE_PURGE_KEY = ""
+
+// CacheProvider creates the server's named in-memory caches, sized from CacheSettings and reporting their hits,
+// misses and evictions to metrics. All of its caches are invalidated through it so that other nodes in the cluster
+// are told about it too.
+type CacheProvider struct {
+	settings model.CacheSettings
+	metrics  einterfaces.MetricsInterface
+	cluster  einterfaces.ClusterInterface
+
+	lock   sync.Mutex
+	caches map[string]*Cache
+}
+
+func NewCacheProvider(settings model.CacheSettings, metrics einterfaces.MetricsInterface, cluster einterfaces.ClusterInterface) *CacheProvider {
+	provider := &CacheProvider{
+		settings: settings,
+		metrics:  metrics,
+		cluster:  cluster,
+		caches:   make(map[string]*Cache),
+	}
+
+	if cluster != nil {
+		cluster.RegisterClusterMessageHandler(model.CLUSTER_EVENT_INVALIDATE_CACHE, provider.handleClusterInvalidate)
+	}
+
+	return provider
+}
+
+// GetCache returns the named cache, creating it with its configured size and expiry the first time that it's used.
+func (p *CacheProvider) GetCache(name string) *Cache {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if cache, ok := p.caches[name]; ok {
+		return cache
+	}
+
+	settings := p.settings.GetCacheSizeSettings(name)
+
+	cache := NewLruWithParams(*settings.Size, name, int64(*settings.ExpirySeconds), model.CLUSTER_EVENT_INVALIDATE_CACHE)
+	cache.metrics = p.metrics
+	p.caches[name] = cache
+
+	return cache
+}
+
+// Invalidate removes the key from the named cache on this server and every other server in the cluster.
+func (p *CacheProvider) Invalidate(name string, key string) {
+	p.InvalidateSkipClusterSend(name, key)
+
+	if p.cluster != nil {
+		p.cluster.SendClusterMessage(&model.ClusterMessage{
+			Event:    model.CLUSTER_EVENT_INVALIDATE_CACHE,
+			SendType: model.CLUSTER_SEND_BEST_EFFORT,
+			Props: map[string]string{
+				"name": name,
+				"key":  key,
+			},
+		})
+	}
+}
+
+// InvalidateSkipClusterSend removes the key from the named cache on this server only. The whole cache is cleared
+// if the key is CACHE_PURGE_KEY.
+func (p *CacheProvider) InvalidateSkipClusterSend(name string, key string) {
+	p.lock.Lock()
+	cache, ok := p.caches[name]
+	p.lock.Unlock()
+
+	if !ok {
+		// Nothing has been cached yet
+		return
+	}
+
+	if key == CACHE_PURGE_KEY {
+		cache.Purge()
+		if p.metrics != nil {
+			p.metrics.IncrementMemCacheInvalidationCounter(name + " - Purge")
+		}
+	} else {
+		cache.Remove(key)
+		if p.metrics != nil {
+			p.metrics.IncrementMemCacheInvalidationCounter(name + " - Remove")
+		}
+	}
+}
+
+// Purge clears the named cache on this server and every other server in the cluster.
+func (p *CacheProvider) Purge(name string) {
+	p.Invalidate(name, CACHE_PURGE_KEY)
+}
+
+// PurgeAllSkipClusterSend clears every cache on this server.
+func (p *CacheProvider) PurgeAllSkipClusterSend() {
+	p.lock.Lock()
+	names := make([]string, 0, len(p.caches))
+	for name := range p.caches {
+		names = append(names, name)
+	}
+	p.lock.Unlock()
+
+	for _, name := range names {
+		p.InvalidateSkipClusterSend(name, CACHE_PURGE_KEY)
+	}
+}
+
+func (p *CacheProvider) handleClusterInvalidate(msg *model.ClusterMessage) {
+	p.InvalidateSkipClusterSend(msg.Props["name"], msg.Props["key"])
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/einterfaces"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+type testCacheMetrics struct {
+	einterfaces.MetricsInterface
+
+	hits      map[string]int
+	misses    map[string]int
+	evictions map[string]int
+}
+
+func newTestCacheMetrics() *testCacheMetrics {
+	return &testCacheMetrics{
+		hits:      map[string]int{},
+		misses:    map[string]int{},
+		evictions: map[string]int{},
+	}
+}
+
+func (m *testCacheMetrics) IncrementMemCacheHitCounter(cacheName string)  { m.hits[cacheName]++ }
+func (m *testCacheMetrics) IncrementMemCacheMissCounter(cacheName string) { m.misses[cacheName]++ }
+func (m *testCacheMetrics) IncrementMemCacheEvictionCounter(cacheName string) {
+	m.evictions[cacheName]++
+}
+func (m *testCacheMetrics) IncrementMemCacheInvalidationCounter(cacheName string) {}
+
+// testCluster delivers the messages sent by one node to the handlers registered by every other node.
+type testCluster struct {
+	einterfaces.ClusterInterface
+
+	nodes    *[]*testCluster
+	handlers map[string]einterfaces.ClusterMessageHandler
+}
+
+func newTestClusters(count int) []*testCluster {
+	nodes := make([]*testCluster, count)
+	for i := range nodes {
+		nodes[i] = &testCluster{nodes: &nodes, handlers: map[string]einterfaces.ClusterMessageHandler{}}
+	}
+	return nodes
+}
+
+func (c *testCluster) RegisterClusterMessageHandler(event string, crm einterfaces.ClusterMessageHandler) {
+	c.handlers[event] = crm
+}
+
+func (c *testCluster) SendClusterMessage(msg *model.ClusterMessage) {
+	for _, node := range *c.nodes {
+		if handler, ok := node.handlers[msg.Event]; ok && node != c {
+			handler(msg)
+		}
+	}
+}
+
+func TestCacheProviderGetCache(t *testing.T) {
+	settings := model.CacheSettings{
+		Caches: map[string]*model.CacheSizeSettings{
+			model.CACHE_CHANNEL_BY_NAME: {Size: model.NewInt(2), ExpirySeconds: model.NewInt(60)},
+		},
+	}
+	metrics := newTestCacheMetrics()
+	provider := NewCacheProvider(settings, metrics, nil)
+
+	cache := provider.GetCache(model.CACHE_CHANNEL_BY_NAME)
+	assert.Equal(t, 2, cache.Size())
+	assert.Equal(t, int64(60), cache.DefaultExpiry())
+	assert.True(t, cache == provider.GetCache(model.CACHE_CHANNEL_BY_NAME), "should reuse the cache")
+
+	defaults := provider.GetCache(model.CACHE_CHANNEL_MEMBER_COUNTS)
+	assert.Equal(t, model.CHANNEL_CACHE_SIZE, defaults.Size(), "should fall back to the default size")
+	assert.Equal(t, int64(30*60), defaults.DefaultExpiry())
+
+	cache.AddWithDefaultExpires("a", 1)
+	cache.AddWithDefaultExpires("b", 2)
+	cache.AddWithDefaultExpires("c", 3)
+	assert.Equal(t, 2, cache.Len(), "should've evicted the oldest item")
+
+	_, ok := cache.Get("a")
+	assert.False(t, ok)
+	_, ok = cache.Get("c")
+	assert.True(t, ok)
+
+	assert.Equal(t, 1, metrics.hits[model.CACHE_CHANNEL_BY_NAME])
+	assert.Equal(t, 1, metrics.misses[model.CACHE_CHANNEL_BY_NAME])
+	assert.Equal(t, 1, metrics.evictions[model.CACHE_CHANNEL_BY_NAME])
+}
+
+func TestCacheProviderInvalidate(t *testing.T) {
+	clusters := newTestClusters(2)
+
+	settings := model.CacheSettings{}
+	settings.SetDefaults()
+
+	local := NewCacheProvider(settings, nil, clusters[0])
+	remote := NewCacheProvider(settings, nil, clusters[1])
+
+	for _, provider := range []*CacheProvider{local, remote} {
+		provider.GetCache(model.CACHE_PROFILE_BY_IDS).Add("user1", 1)
+		provider.GetCache(model.CACHE_PROFILE_BY_IDS).Add("user2", 2)
+	}
+
+	local.Invalidate(model.CACHE_PROFILE_BY_IDS, "user1")
+	for _, provider := range []*CacheProvider{local, remote} {
+		_, ok := provider.GetCache(model.CACHE_PROFILE_BY_IDS).Get("user1")
+		assert.False(t, ok)
+		_, ok = provider.GetCache(model.CACHE_PROFILE_BY_IDS).Get("user2")
+		assert.True(t, ok)
+	}
+
+	remote.InvalidateSkipClusterSend(model.CACHE_PROFILE_BY_IDS, "user2")
+	_, ok := local.GetCache(model.CACHE_PROFILE_BY_IDS).Get("user2")
+	assert.True(t, ok, "shouldn't have told other nodes")
+
+	remote.Purge(model.CACHE_PROFILE_BY_IDS)
+	assert.Equal(t, 0, local.GetCache(model.CACHE_PROFILE_BY_IDS).Len())
+
+	// Invalidating a cache that hasn't been used yet does nothing
+	require.NotPanics(t, func() {
+		local.Invalidate(model.CACHE_CHANNEL_BY_NAME, "name")
+	})
+}
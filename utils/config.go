@@ -5,6 +5,7 @@ package utils
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,6 +15,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/pkg/errors"
@@ -110,50 +112,87 @@ func SaveConfig(fileName string, config *model.Config) *model.AppError {
 	return nil
 }
 
+// ConfigWatcher reloads a config file whenever it changes. Bursts of changes, such as an editor writing the file in
+// several parts, are only reloaded once they've settled, and a file that doesn't parse or isn't valid is never loaded.
 type ConfigWatcher struct {
-	watcher *fsnotify.Watcher
-	close   chan struct{}
-	closed  chan struct{}
+	watcher    *fsnotify.Watcher
+	close      chan struct{}
+	closed     chan struct{}
+	configFile string
+	debounce   time.Duration
+	onChange   func(*model.Config, map[string]interface{})
+
+	// hash is of the contents of the file that was last loaded, so that changes that leave the contents as they were
+	// can be ignored.
+	hash [sha256.Size]byte
+
+	// lastError is the reason that the file last failed to load, so that it's only logged once while the file stays
+	// broken.
+	lastError string
 }
 
-func NewConfigWatcher(cfgFileName string, f func()) (*ConfigWatcher, error) {
+// NewConfigWatcher watches the config file at cfgFileName, calling f with the new config once the file has been left
+// unchanged for the debounce period and the new contents have loaded successfully.
+func NewConfigWatcher(cfgFileName string, debounce time.Duration, f func(*model.Config, map[string]interface{})) (*ConfigWatcher, error) {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to create config watcher for file: "+cfgFileName)
+		return nil, errors.Wrapf(err, "failed to create config watcher for file: %v", cfgFileName)
 	}
 
 	configFile := filepath.Clean(cfgFileName)
 	configDir, _ := filepath.Split(configFile)
 	watcher.Add(configDir)
 
+	// The file is watched as well as its directory so that changes are still seen when it's a link to a file
+	// elsewhere
+	watcher.Add(configFile)
+
 	ret := &ConfigWatcher{
-		watcher: watcher,
-		close:   make(chan struct{}),
-		closed:  make(chan struct{}),
+		watcher:    watcher,
+		close:      make(chan struct{}),
+		closed:     make(chan struct{}),
+		configFile: configFile,
+		debounce:   debounce,
+		onChange:   f,
+	}
+
+	if data, err := ioutil.ReadFile(configFile); err == nil {
+		ret.hash = sha256.Sum256(data)
 	}
 
 	go func() {
 		defer close(ret.closed)
 		defer watcher.Close()
 
+		var timer *time.Timer
+		var reload <-chan time.Time
+
 		for {
 			select {
 			case event := <-watcher.Events:
 				// we only care about the config file
-				if filepath.Clean(event.Name) == configFile {
-					if event.Op&fsnotify.Write == fsnotify.Write || event.Op&fsnotify.Create == fsnotify.Create {
-						mlog.Info(fmt.Sprintf("Config file watcher detected a change reloading %v", cfgFileName))
-
-						if _, _, configReadErr := ReadConfigFile(cfgFileName, true); configReadErr == nil {
-							f()
-						} else {
-							mlog.Error(fmt.Sprintf("Failed to read while watching config file at %v with err=%v", cfgFileName, configReadErr.Error()))
-						}
-					}
+				if filepath.Clean(event.Name) != configFile {
+					continue
+				}
+
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) == 0 {
+					continue
 				}
+
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.NewTimer(debounce)
+				reload = timer.C
+			case <-reload:
+				reload = nil
+				ret.reload()
 			case err := <-watcher.Errors:
 				mlog.Error(fmt.Sprintf("Failed while watching config file at %v with err=%v", cfgFileName, err.Error()))
 			case <-ret.close:
+				if timer != nil {
+					timer.Stop()
+				}
 				return
 			}
 		}
@@ -162,6 +201,44 @@ func NewConfigWatcher(cfgFileName string, f func()) (*ConfigWatcher, error) {
 	return ret, nil
 }
 
+func (w *ConfigWatcher) reload() {
+	// Saving by renaming a new file over the old one removes the watch on it, so it's added again for the new file
+	w.watcher.Add(w.configFile)
+
+	data, err := ioutil.ReadFile(w.configFile)
+	if err != nil {
+		w.reloadFailed(err.Error())
+		return
+	}
+
+	hash := sha256.Sum256(data)
+	if hash == w.hash {
+		w.lastError = ""
+		return
+	}
+
+	mlog.Info(fmt.Sprintf("Config file watcher detected a change reloading %v", w.configFile))
+
+	config, envConfig, appErr := LoadConfigData(w.configFile, data)
+	if appErr != nil {
+		w.reloadFailed(appErr.Error())
+		return
+	}
+
+	w.hash = hash
+	w.lastError = ""
+	w.onChange(config, envConfig)
+}
+
+func (w *ConfigWatcher) reloadFailed(reason string) {
+	if reason == w.lastError {
+		return
+	}
+
+	w.lastError = reason
+	mlog.Error(fmt.Sprintf("Failed to reload config file at %v, keeping the current config, err=%v", w.configFile, reason))
+}
+
 func (w *ConfigWatcher) Close() {
 	close(w.close)
 	<-w.closed
@@ -397,19 +474,37 @@ func LoadConfig(fileName string) (*model.Config, string, map[string]interface{},
 		}
 	}
 
-	config, envConfig, err := ReadConfigFile(configPath, true)
+	data, err := ioutil.ReadFile(configPath)
 	if err != nil {
 		appErr := model.NewAppError("LoadConfig", "utils.config.load_config.decoding.panic", map[string]interface{}{"Filename": fileName, "Error": err.Error()}, "", 0)
 		return nil, "", nil, appErr
 	}
 
+	config, envConfig, appErr := LoadConfigData(configPath, data)
+	if appErr != nil {
+		return nil, "", nil, appErr
+	}
+
+	return config, configPath, envConfig, nil
+}
+
+// LoadConfigData parses and validates the contents of the config file at configPath, filling in defaults and saving
+// the file again if it was missing any generated keys.
+func LoadConfigData(configPath string, data []byte) (*model.Config, map[string]interface{}, *model.AppError) {
+	config, envConfig, err := ReadConfig(bytes.NewReader(data), true)
+	if err != nil {
+		appErr := model.NewAppError("LoadConfig", "utils.config.load_config.decoding.panic", map[string]interface{}{"Filename": configPath, "Error": err.Error()}, "", 0)
+		return nil, nil, appErr
+	}
+
 	needSave := len(config.SqlSettings.AtRestEncryptKey) == 0 || len(*config.FileSettings.PublicLinkSalt) == 0 ||
-		len(config.EmailSettings.InviteSalt) == 0
+		len(config.EmailSettings.InviteSalt) == 0 || config.ServiceSettings.PostActionSigningSecret == nil ||
+		len(*config.ServiceSettings.PostActionSigningSecret) == 0
 
 	config.SetDefaults()
 
 	if err := config.IsValid(); err != nil {
-		return nil, "", nil, err
+		return nil, nil, err
 	}
 
 	if needSave {
@@ -431,7 +526,7 @@ func LoadConfig(fileName string) (*model.Config, string, map[string]interface{},
 		}
 	}
 
-	return config, configPath, envConfig, nil
+	return config, envConfig, nil
 }
 
 func GenerateClientConfig(c *model.Config, diagnosticId string, license *model.License) map[string]string {
@@ -479,13 +574,20 @@ func GenerateClientConfig(c *model.Config, diagnosticId string, license *model.L
 	props["EnablePostUsernameOverride"] = strconv.FormatBool(c.ServiceSettings.EnablePostUsernameOverride)
 	props["EnablePostIconOverride"] = strconv.FormatBool(c.ServiceSettings.EnablePostIconOverride)
 	props["EnableUserAccessTokens"] = strconv.FormatBool(*c.ServiceSettings.EnableUserAccessTokens)
+	props["EnableUserBlocking"] = strconv.FormatBool(*c.ServiceSettings.EnableUserBlocking)
 	props["EnableLinkPreviews"] = strconv.FormatBool(*c.ServiceSettings.EnableLinkPreviews)
+	props["EnablePermalinkPreviews"] = strconv.FormatBool(*c.ServiceSettings.EnablePermalinkPreviews)
 	props["EnableTesting"] = strconv.FormatBool(c.ServiceSettings.EnableTesting)
 	props["EnableDeveloper"] = strconv.FormatBool(*c.ServiceSettings.EnableDeveloper)
 	props["EnableDiagnostics"] = strconv.FormatBool(*c.LogSettings.EnableDiagnostics)
 	props["RestrictPostDelete"] = *c.ServiceSettings.RestrictPostDelete
 	props["AllowEditPost"] = *c.ServiceSettings.AllowEditPost
 	props["PostEditTimeLimit"] = fmt.Sprintf("%v", *c.ServiceSettings.PostEditTimeLimit)
+	props["PostEditTimeLimitByRole"] = timeLimitsByRoleToJson(c.ServiceSettings.PostEditTimeLimitByRole)
+	props["PostDeleteTimeLimit"] = fmt.Sprintf("%v", *c.ServiceSettings.PostDeleteTimeLimit)
+	props["PostDeleteTimeLimitByRole"] = timeLimitsByRoleToJson(c.ServiceSettings.PostDeleteTimeLimitByRole)
+	props["UniqueEmojiReactionLimitPerPost"] = strconv.Itoa(*c.ServiceSettings.UniqueEmojiReactionLimitPerPost)
+	props["MaxPostPropsSize"] = strconv.Itoa(*c.ServiceSettings.MaxPostPropsSize)
 	props["CloseUnusedDirectMessages"] = strconv.FormatBool(*c.ServiceSettings.CloseUnusedDirectMessages)
 	props["EnablePreviewFeatures"] = strconv.FormatBool(*c.ServiceSettings.EnablePreviewFeatures)
 	props["EnableTutorial"] = strconv.FormatBool(*c.ServiceSettings.EnableTutorial)
@@ -493,6 +595,7 @@ func GenerateClientConfig(c *model.Config, diagnosticId string, license *model.L
 	props["ExperimentalGroupUnreadChannels"] = *c.ServiceSettings.ExperimentalGroupUnreadChannels
 	props["ExperimentalEnableAutomaticReplies"] = strconv.FormatBool(*c.TeamSettings.ExperimentalEnableAutomaticReplies)
 	props["ExperimentalTimezone"] = strconv.FormatBool(*c.DisplaySettings.ExperimentalTimezone)
+	props["EnableGuestAccounts"] = strconv.FormatBool(*c.GuestAccountsSettings.Enable)
 
 	props["SendEmailNotifications"] = strconv.FormatBool(c.EmailSettings.SendEmailNotifications)
 	props["SendPushNotifications"] = strconv.FormatBool(*c.EmailSettings.SendPushNotifications)
@@ -510,6 +613,10 @@ func GenerateClientConfig(c *model.Config, diagnosticId string, license *model.L
 
 	props["EnableSignUpWithGitLab"] = strconv.FormatBool(c.GitLabSettings.Enable)
 
+	props["EnableLoginCaptcha"] = strconv.FormatBool(*c.CaptchaSettings.Enable)
+	props["CaptchaProvider"] = *c.CaptchaSettings.Provider
+	props["CaptchaSiteKey"] = *c.CaptchaSettings.SiteKey
+
 	props["ShowEmailAddress"] = strconv.FormatBool(c.PrivacySettings.ShowEmailAddress)
 
 	props["TermsOfServiceLink"] = *c.SupportSettings.TermsOfServiceLink
@@ -518,6 +625,7 @@ func GenerateClientConfig(c *model.Config, diagnosticId string, license *model.L
 	props["HelpLink"] = *c.SupportSettings.HelpLink
 	props["ReportAProblemLink"] = *c.SupportSettings.ReportAProblemLink
 	props["SupportEmail"] = *c.SupportSettings.SupportEmail
+	props["CustomTermsOfServiceEnabled"] = strconv.FormatBool(*c.SupportSettings.CustomTermsOfServiceEnabled)
 
 	props["EnableFileAttachments"] = strconv.FormatBool(*c.FileSettings.EnableFileAttachments)
 	props["EnablePublicLink"] = strconv.FormatBool(c.FileSettings.EnablePublicLink)
@@ -533,6 +641,7 @@ func GenerateClientConfig(c *model.Config, diagnosticId string, license *model.L
 	props["EnableEmojiPicker"] = strconv.FormatBool(*c.ServiceSettings.EnableEmojiPicker)
 	props["RestrictCustomEmojiCreation"] = *c.ServiceSettings.RestrictCustomEmojiCreation
 	props["MaxFileSize"] = strconv.FormatInt(*c.FileSettings.MaxFileSize, 10)
+	props["MaxFileAttachments"] = strconv.Itoa(*c.FileSettings.MaxFileAttachments)
 	props["AppDownloadLink"] = *c.NativeAppSettings.AppDownloadLink
 	props["AndroidAppDownloadLink"] = *c.NativeAppSettings.AndroidAppDownloadLink
 	props["IosAppDownloadLink"] = *c.NativeAppSettings.IosAppDownloadLink
@@ -540,17 +649,22 @@ func GenerateClientConfig(c *model.Config, diagnosticId string, license *model.L
 	props["EnableWebrtc"] = strconv.FormatBool(*c.WebrtcSettings.Enable)
 
 	props["MaxNotificationsPerChannel"] = strconv.FormatInt(*c.TeamSettings.MaxNotificationsPerChannel, 10)
+	props["MaxChannelHeaderLength"] = strconv.Itoa(*c.TeamSettings.MaxChannelHeaderLength)
+	props["MaxChannelPurposeLength"] = strconv.Itoa(*c.TeamSettings.MaxChannelPurposeLength)
 	props["EnableConfirmNotificationsToChannel"] = strconv.FormatBool(*c.TeamSettings.EnableConfirmNotificationsToChannel)
+	props["EnablePrivateToPublicConversion"] = strconv.FormatBool(*c.TeamSettings.EnablePrivateToPublicConversion)
 	props["TimeBetweenUserTypingUpdatesMilliseconds"] = strconv.FormatInt(*c.ServiceSettings.TimeBetweenUserTypingUpdatesMilliseconds, 10)
 	props["EnableUserTypingMessages"] = strconv.FormatBool(*c.ServiceSettings.EnableUserTypingMessages)
 	props["EnableChannelViewedMessages"] = strconv.FormatBool(*c.ServiceSettings.EnableChannelViewedMessages)
+	props["EnableChannelFeeds"] = strconv.FormatBool(*c.ServiceSettings.EnableChannelFeeds)
 
 	props["DiagnosticId"] = diagnosticId
 	props["DiagnosticsEnabled"] = strconv.FormatBool(*c.LogSettings.EnableDiagnostics)
 
 	props["PluginsEnabled"] = strconv.FormatBool(*c.PluginSettings.Enable)
 
-	hasImageProxy := c.ServiceSettings.ImageProxyType != nil && *c.ServiceSettings.ImageProxyType != "" && c.ServiceSettings.ImageProxyURL != nil && *c.ServiceSettings.ImageProxyURL != ""
+	hasImageProxy := c.ServiceSettings.ImageProxyType != nil && *c.ServiceSettings.ImageProxyType != "" &&
+		(*c.ServiceSettings.ImageProxyType == model.IMAGE_PROXY_TYPE_LOCAL || (c.ServiceSettings.ImageProxyURL != nil && *c.ServiceSettings.ImageProxyURL != ""))
 	props["HasImageProxy"] = strconv.FormatBool(hasImageProxy)
 
 	// Set default values for all options that require a license.
@@ -609,6 +723,10 @@ func GenerateClientConfig(c *model.Config, diagnosticId string, license *model.L
 	props["PasswordRequireNumber"] = strconv.FormatBool(*c.PasswordSettings.Number)
 	props["PasswordRequireSymbol"] = strconv.FormatBool(*c.PasswordSettings.Symbol)
 
+	for name, value := range c.FeatureFlags.ToMap() {
+		props[model.FEATURE_FLAG_CLIENT_CONFIG_PREFIX+name] = value
+	}
+
 	if license != nil {
 		props["ExperimentalHideTownSquareinLHS"] = strconv.FormatBool(*c.TeamSettings.ExperimentalHideTownSquareinLHS)
 		props["ExperimentalTownSquareIsReadOnly"] = strconv.FormatBool(*c.TeamSettings.ExperimentalTownSquareIsReadOnly)
@@ -695,6 +813,15 @@ func GenerateClientConfig(c *model.Config, diagnosticId string, license *model.L
 	return props
 }
 
+func timeLimitsByRoleToJson(limits map[string]int) string {
+	if limits == nil {
+		return "{}"
+	}
+
+	b, _ := json.Marshal(limits)
+	return string(b)
+}
+
 func ValidateLdapFilter(cfg *model.Config, ldap einterfaces.LdapInterface) *model.AppError {
 	if *cfg.LdapSettings.Enable && ldap != nil && *cfg.LdapSettings.UserFilter != "" {
 		if err := ldap.ValidateFilter(*cfg.LdapSettings.UserFilter); err != nil {
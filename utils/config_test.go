@@ -5,11 +5,13 @@ package utils
 
 import (
 	"bytes"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -62,6 +64,117 @@ func TestFindConfigFile(t *testing.T) {
 	assert.Equal(t, path, FindConfigFile(path))
 }
 
+func setupWatchedConfig(t *testing.T) (string, *model.Config, func()) {
+	TranslationsPreInit()
+	cfg, _, _, appErr := LoadConfig("config.json")
+	require.Nil(t, appErr)
+
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "config.json")
+	require.Nil(t, SaveConfig(path, cfg))
+
+	return path, cfg, func() { os.RemoveAll(dir) }
+}
+
+func watchConfig(t *testing.T, path string, debounce time.Duration) (chan *model.Config, func()) {
+	reloads := make(chan *model.Config, 10)
+	watcher, err := NewConfigWatcher(path, debounce, func(cfg *model.Config, envConfig map[string]interface{}) {
+		reloads <- cfg
+	})
+	require.NoError(t, err)
+
+	return reloads, watcher.Close
+}
+
+func configWithSiteName(cfg *model.Config, siteName string) []byte {
+	cfg.TeamSettings.SiteName = siteName
+	return []byte(cfg.ToJson())
+}
+
+func waitForConfigReload(t *testing.T, reloads chan *model.Config) *model.Config {
+	select {
+	case cfg := <-reloads:
+		return cfg
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timed out waiting for the config to be reloaded")
+		return nil
+	}
+}
+
+func assertNoConfigReload(t *testing.T, reloads chan *model.Config, wait time.Duration) {
+	select {
+	case cfg := <-reloads:
+		assert.Fail(t, "the config shouldn't have been reloaded", "SiteName=%v", cfg.TeamSettings.SiteName)
+	case <-time.After(wait):
+	}
+}
+
+func TestConfigWatcher(t *testing.T) {
+	debounce := 200 * time.Millisecond
+
+	t.Run("partial writes", func(t *testing.T) {
+		path, cfg, cleanup := setupWatchedConfig(t)
+		defer cleanup()
+
+		reloads, stop := watchConfig(t, path, debounce)
+		defer stop()
+
+		// Each version of the file is written in two parts, leaving it invalid in between
+		for i := 0; i < 5; i++ {
+			data := configWithSiteName(cfg, fmt.Sprintf("Site %v", i))
+
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0600)
+			require.NoError(t, err)
+			_, err = f.Write(data[:len(data)/2])
+			require.NoError(t, err)
+			time.Sleep(10 * time.Millisecond)
+			_, err = f.Write(data[len(data)/2:])
+			require.NoError(t, err)
+			require.NoError(t, f.Close())
+
+			time.Sleep(20 * time.Millisecond)
+		}
+
+		assert.Equal(t, "Site 4", waitForConfigReload(t, reloads).TeamSettings.SiteName)
+		assertNoConfigReload(t, reloads, 3*debounce)
+
+		// An invalid file is never loaded
+		data := configWithSiteName(cfg, "Site 4")
+		require.NoError(t, ioutil.WriteFile(path, data[:len(data)/2], 0600))
+		assertNoConfigReload(t, reloads, 3*debounce)
+
+		// Nor is one that is the same as what was last loaded
+		require.NoError(t, ioutil.WriteFile(path, data, 0600))
+		assertNoConfigReload(t, reloads, 3*debounce)
+
+		require.NoError(t, ioutil.WriteFile(path, configWithSiteName(cfg, "Site 5"), 0600))
+		assert.Equal(t, "Site 5", waitForConfigReload(t, reloads).TeamSettings.SiteName)
+	})
+
+	t.Run("saved by renaming", func(t *testing.T) {
+		path, cfg, cleanup := setupWatchedConfig(t)
+		defer cleanup()
+
+		reloads, stop := watchConfig(t, path, debounce)
+		defer stop()
+
+		for i := 0; i < 3; i++ {
+			siteName := fmt.Sprintf("Renamed %v", i)
+			require.NoError(t, ioutil.WriteFile(path+".tmp", configWithSiteName(cfg, siteName), 0600))
+			require.NoError(t, os.Rename(path+".tmp", path))
+
+			assert.Equal(t, siteName, waitForConfigReload(t, reloads).TeamSettings.SiteName)
+		}
+
+		// The replaced file is still watched
+		require.NoError(t, ioutil.WriteFile(path, configWithSiteName(cfg, "Written"), 0600))
+		assert.Equal(t, "Written", waitForConfigReload(t, reloads).TeamSettings.SiteName)
+		assertNoConfigReload(t, reloads, 3*debounce)
+	})
+}
+
 func TestConfigFromEnviroVars(t *testing.T) {
 	TranslationsPreInit()
 
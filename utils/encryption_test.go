@@ -0,0 +1,35 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestEncryptAtRest(t *testing.T) {
+	key := model.NewRandomString(32)
+
+	encrypted, err := EncryptAtRest(key, []byte("secret"))
+	require.NoError(t, err)
+	assert.NotContains(t, encrypted, "secret")
+
+	again, err := EncryptAtRest(key, []byte("secret"))
+	require.NoError(t, err)
+	assert.NotEqual(t, encrypted, again, "each encryption should use a new nonce")
+
+	decrypted, err := DecryptAtRest(key, encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(decrypted))
+
+	_, err = DecryptAtRest(model.NewRandomString(32), encrypted)
+	assert.Error(t, err, "a different key shouldn't decrypt it")
+
+	_, err = DecryptAtRest(key, "c2hvcnQ=")
+	assert.Error(t, err)
+}
@@ -14,12 +14,16 @@ type FileBackend interface {
 	TestConnection() *model.AppError
 
 	ReadFile(path string) ([]byte, *model.AppError)
+	FileExists(path string) (bool, *model.AppError)
 	CopyFile(oldPath, newPath string) *model.AppError
 	MoveFile(oldPath, newPath string) *model.AppError
 	WriteFile(fr io.Reader, path string) (int64, *model.AppError)
 	RemoveFile(path string) *model.AppError
 
 	ListDirectory(path string) (*[]string, *model.AppError)
+	// WalkFiles calls walkFn with the path of each file in the directory and its subdirectories, stopping early if it
+	// returns false.
+	WalkFiles(path string, walkFn func(path string) bool) *model.AppError
 	RemoveDirectory(path string) *model.AppError
 }
 
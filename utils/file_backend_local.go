@@ -5,6 +5,7 @@ package utils
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -41,6 +42,15 @@ func (b *LocalFileBackend) ReadFile(path string) ([]byte, *model.AppError) {
 	}
 }
 
+func (b *LocalFileBackend) FileExists(path string) (bool, *model.AppError) {
+	if _, err := os.Stat(filepath.Join(b.directory, path)); os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, model.NewAppError("FileExists", "utils.file.file_exists.local.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+	return true, nil
+}
+
 func (b *LocalFileBackend) CopyFile(oldPath, newPath string) *model.AppError {
 	if err := CopyFile(filepath.Join(b.directory, oldPath), filepath.Join(b.directory, newPath)); err != nil {
 		return model.NewAppError("copyFile", "api.file.move_file.rename.app_error", nil, err.Error(), http.StatusInternalServerError)
@@ -102,6 +112,38 @@ func (b *LocalFileBackend) ListDirectory(path string) (*[]string, *model.AppErro
 	return &paths, nil
 }
 
+func (b *LocalFileBackend) WalkFiles(path string, walkFn func(path string) bool) *model.AppError {
+	errStop := errors.New("stopped")
+
+	root := filepath.Join(b.directory, path)
+	err := filepath.Walk(root, func(filePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			// A directory that doesn't exist has no files, as with S3
+			if filePath == root && os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		} else if fileInfo.IsDir() {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(b.directory, filePath)
+		if err != nil {
+			return err
+		}
+
+		if !walkFn(filepath.ToSlash(relativePath)) {
+			return errStop
+		}
+		return nil
+	})
+	if err != nil && err != errStop {
+		return model.NewAppError("WalkFiles", "utils.file.walk_files.local.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
 func (b *LocalFileBackend) RemoveDirectory(path string) *model.AppError {
 	if err := os.RemoveAll(filepath.Join(b.directory, path)); err != nil {
 		return model.NewAppError("RemoveDirectory", "utils.file.remove_directory.local.app_error", nil, err.Error(), http.StatusInternalServerError)
@@ -99,6 +99,22 @@ func (b *S3FileBackend) ReadFile(path string) ([]byte, *model.AppError) {
 	}
 }
 
+func (b *S3FileBackend) FileExists(path string) (bool, *model.AppError) {
+	s3Clnt, err := b.s3New()
+	if err != nil {
+		return false, model.NewAppError("FileExists", "utils.file.file_exists.s3.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	if _, err := s3Clnt.StatObject(b.bucket, path, s3.StatObjectOptions{}); err != nil {
+		if s3.ToErrorResponse(err).Code == "NoSuchKey" {
+			return false, nil
+		}
+		return false, model.NewAppError("FileExists", "utils.file.file_exists.s3.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	return true, nil
+}
+
 func (b *S3FileBackend) CopyFile(oldPath, newPath string) *model.AppError {
 	s3Clnt, err := b.s3New()
 	if err != nil {
@@ -213,6 +229,33 @@ func (b *S3FileBackend) ListDirectory(path string) (*[]string, *model.AppError)
 	return &paths, nil
 }
 
+func (b *S3FileBackend) WalkFiles(path string, walkFn func(path string) bool) *model.AppError {
+	s3Clnt, err := b.s3New()
+	if err != nil {
+		return model.NewAppError("WalkFiles", "utils.file.walk_files.s3.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	doneCh := make(chan struct{})
+
+	defer close(doneCh)
+
+	prefix := path
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	for object := range s3Clnt.ListObjects(b.bucket, prefix, true, doneCh) {
+		if object.Err != nil {
+			return model.NewAppError("WalkFiles", "utils.file.walk_files.s3.app_error", nil, object.Err.Error(), http.StatusInternalServerError)
+		}
+		if !walkFn(object.Key) {
+			break
+		}
+	}
+
+	return nil
+}
+
 func (b *S3FileBackend) RemoveDirectory(path string) *model.AppError {
 	s3Clnt, err := b.s3New()
 	if err != nil {
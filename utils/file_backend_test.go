@@ -124,6 +124,24 @@ func (s *FileBackendTestSuite) TestReadWriteFileImage() {
 	s.EqualValues(readString, "testimage")
 }
 
+func (s *FileBackendTestSuite) TestFileExists() {
+	b := []byte("test")
+	path := "tests/" + model.NewId()
+
+	exists, err := s.backend.FileExists(path)
+	s.Nil(err)
+	s.False(exists)
+
+	written, err := s.backend.WriteFile(bytes.NewReader(b), path)
+	s.Nil(err)
+	s.EqualValues(len(b), written, "expected given number of bytes to have been written")
+	defer s.backend.RemoveFile(path)
+
+	exists, err = s.backend.FileExists(path)
+	s.Nil(err)
+	s.True(exists)
+}
+
 func (s *FileBackendTestSuite) TestCopyFile() {
 	b := []byte("test")
 	path1 := "tests/" + model.NewId()
@@ -244,6 +262,38 @@ func (s *FileBackendTestSuite) TestListDirectory() {
 	s.True(found2)
 }
 
+func (s *FileBackendTestSuite) TestWalkFiles() {
+	b := []byte("test")
+	directory := "tests/" + model.NewId()
+	paths := []string{directory + "/foo", directory + "/bar/baz", directory + "/bar/qux/quux"}
+
+	for _, path := range paths {
+		written, err := s.backend.WriteFile(bytes.NewReader(b), path)
+		s.Nil(err)
+		s.EqualValues(len(b), written, "expected given number of bytes to have been written")
+	}
+	defer s.backend.RemoveDirectory(directory)
+
+	var walked []string
+	s.Nil(s.backend.WalkFiles(directory, func(path string) bool {
+		walked = append(walked, path)
+		return true
+	}))
+	s.ElementsMatch(paths, walked)
+
+	walked = nil
+	s.Nil(s.backend.WalkFiles(directory, func(path string) bool {
+		walked = append(walked, path)
+		return false
+	}))
+	s.Len(walked, 1, "walking should stop when the function returns false")
+
+	s.Nil(s.backend.WalkFiles("tests/"+model.NewId(), func(path string) bool {
+		s.Fail("there shouldn't be any files in a directory that doesn't exist")
+		return true
+	}))
+}
+
 func (s *FileBackendTestSuite) TestRemoveDirectory() {
 	b := []byte("test")
 
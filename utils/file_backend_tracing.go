@@ -0,0 +1,66 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package utils
+
+import (
+	"io"
+
+	"github.com/mattermost/mattermost-server/model"
+	"github.com/mattermost/mattermost-server/utils/tracing"
+)
+
+// TracingFileBackend times the calls made to a FileBackend for the traces of the requests making them.
+type TracingFileBackend struct {
+	FileBackend
+}
+
+func (b *TracingFileBackend) TestConnection() *model.AppError {
+	defer tracing.StartSpan(model.REQUEST_TRACE_SPAN_FILESTORE, "TestConnection")()
+	return b.FileBackend.TestConnection()
+}
+
+func (b *TracingFileBackend) ReadFile(path string) ([]byte, *model.AppError) {
+	defer tracing.StartSpan(model.REQUEST_TRACE_SPAN_FILESTORE, "ReadFile")()
+	return b.FileBackend.ReadFile(path)
+}
+
+func (b *TracingFileBackend) FileExists(path string) (bool, *model.AppError) {
+	defer tracing.StartSpan(model.REQUEST_TRACE_SPAN_FILESTORE, "FileExists")()
+	return b.FileBackend.FileExists(path)
+}
+
+func (b *TracingFileBackend) CopyFile(oldPath, newPath string) *model.AppError {
+	defer tracing.StartSpan(model.REQUEST_TRACE_SPAN_FILESTORE, "CopyFile")()
+	return b.FileBackend.CopyFile(oldPath, newPath)
+}
+
+func (b *TracingFileBackend) MoveFile(oldPath, newPath string) *model.AppError {
+	defer tracing.StartSpan(model.REQUEST_TRACE_SPAN_FILESTORE, "MoveFile")()
+	return b.FileBackend.MoveFile(oldPath, newPath)
+}
+
+func (b *TracingFileBackend) WriteFile(fr io.Reader, path string) (int64, *model.AppError) {
+	defer tracing.StartSpan(model.REQUEST_TRACE_SPAN_FILESTORE, "WriteFile")()
+	return b.FileBackend.WriteFile(fr, path)
+}
+
+func (b *TracingFileBackend) RemoveFile(path string) *model.AppError {
+	defer tracing.StartSpan(model.REQUEST_TRACE_SPAN_FILESTORE, "RemoveFile")()
+	return b.FileBackend.RemoveFile(path)
+}
+
+func (b *TracingFileBackend) ListDirectory(path string) (*[]string, *model.AppError) {
+	defer tracing.StartSpan(model.REQUEST_TRACE_SPAN_FILESTORE, "ListDirectory")()
+	return b.FileBackend.ListDirectory(path)
+}
+
+func (b *TracingFileBackend) WalkFiles(path string, walkFn func(path string) bool) *model.AppError {
+	defer tracing.StartSpan(model.REQUEST_TRACE_SPAN_FILESTORE, "WalkFiles")()
+	return b.FileBackend.WalkFiles(path, walkFn)
+}
+
+func (b *TracingFileBackend) RemoveDirectory(path string) *model.AppError {
+	defer tracing.StartSpan(model.REQUEST_TRACE_SPAN_FILESTORE, "RemoveDirectory")()
+	return b.FileBackend.RemoveDirectory(path)
+}
@@ -118,3 +118,24 @@ func TestDialContextFilter(t *testing.T) {
 		}
 	}
 }
+
+func TestIsReservedIP(t *testing.T) {
+	for _, tc := range []struct {
+		IP         string
+		IsReserved bool
+	}{
+		{"8.8.8.8", false},
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.100.100.200", true},
+		{"::ffff:127.0.0.1", true},
+		{"fd00:ec2::254", true},
+		{"2001:4860:4860::8888", false},
+	} {
+		if IsReservedIP(net.ParseIP(tc.IP)) != tc.IsReserved {
+			t.Errorf("unexpected result for %v", tc.IP)
+		}
+	}
+}
@@ -4,10 +4,12 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/mattermost/mattermost-server/mlog"
@@ -67,8 +69,9 @@ func InitTranslationsWithDir(dir string) error {
 func GetTranslationsBySystemLocale() (i18n.TranslateFunc, error) {
 	locale := *settings.DefaultServerLocale
 	if _, ok := locales[locale]; !ok {
-		mlog.Error(fmt.Sprintf("Failed to load system translations for '%v' attempting to fall back to '%v'", locale, model.DEFAULT_LOCALE))
-		locale = model.DEFAULT_LOCALE
+		fallback := localeFallbacks(locale)[0]
+		mlog.Error(fmt.Sprintf("Failed to load system translations for '%v' attempting to fall back to '%v'", locale, fallback))
+		locale = fallback
 	}
 
 	if locales[locale] == "" {
@@ -85,12 +88,7 @@ func GetTranslationsBySystemLocale() (i18n.TranslateFunc, error) {
 }
 
 func GetUserTranslations(locale string) i18n.TranslateFunc {
-	if _, ok := locales[locale]; !ok {
-		locale = model.DEFAULT_LOCALE
-	}
-
-	translations := TfuncWithFallback(locale)
-	return translations
+	return TfuncWithFallback(locale)
 }
 
 func GetTranslationsAndLocale(w http.ResponseWriter, r *http.Request) (i18n.TranslateFunc, string) {
@@ -99,12 +97,12 @@ func GetTranslationsAndLocale(w http.ResponseWriter, r *http.Request) (i18n.Tran
 	// This is for checking against locales like en, es
 	headerLocale := strings.Split(strings.Split(r.Header.Get("Accept-Language"), ",")[0], "-")[0]
 	defaultLocale := *settings.DefaultClientLocale
-	if locales[headerLocaleFull] != "" {
-		translations := TfuncWithFallback(headerLocaleFull)
-		return translations, headerLocaleFull
-	} else if locales[headerLocale] != "" {
-		translations := TfuncWithFallback(headerLocale)
-		return translations, headerLocale
+	if locale := findLocale(headerLocaleFull); locale != "" {
+		translations := TfuncWithFallback(locale)
+		return translations, locale
+	} else if locale := findLocale(headerLocale); locale != "" {
+		translations := TfuncWithFallback(locale)
+		return translations, locale
 	} else if locales[defaultLocale] != "" {
 		translations := TfuncWithFallback(defaultLocale)
 		return translations, headerLocale
@@ -118,6 +116,9 @@ func GetSupportedLocales() map[string]string {
 	return locales
 }
 
+// TfuncWithFallback returns a function that translates into the preferred locale. Messages that the locale doesn't
+// have are translated into the language that it's a variant of, such as pt for pt-BR, and then into English, so that
+// users are never shown the ids of messages that have been translated into English.
 func TfuncWithFallback(pref string) i18n.TranslateFunc {
 	t, _ := i18n.Tfunc(pref)
 	return func(translationID string, args ...interface{}) string {
@@ -125,7 +126,130 @@ func TfuncWithFallback(pref string) i18n.TranslateFunc {
 			return translated
 		}
 
-		t, _ := i18n.Tfunc(model.DEFAULT_LOCALE)
-		return t(translationID, args...)
+		// The fallbacks are found when they're needed since T and TDefault are set before the translations are loaded
+		for _, locale := range localeFallbacks(pref) {
+			t, _ := i18n.Tfunc(locale)
+			if translated := t(translationID, args...); translated != translationID {
+				return translated
+			}
+		}
+
+		return translationID
+	}
+}
+
+// localeFallbacks returns the loaded locales that messages are translated into for the given locale, in the order
+// that they're tried: the locale itself, the language that it's a variant of and English.
+func localeFallbacks(locale string) []string {
+	var fallbacks []string
+	add := func(name string) {
+		loaded := findLocale(name)
+		if loaded == "" {
+			return
+		}
+
+		for _, fallback := range fallbacks {
+			if fallback == loaded {
+				return
+			}
+		}
+		fallbacks = append(fallbacks, loaded)
+	}
+
+	add(locale)
+	if i := strings.IndexAny(locale, "-_"); i > 0 {
+		add(locale[:i])
+	}
+	add(model.DEFAULT_LOCALE)
+
+	// The translations haven't been loaded yet
+	if len(fallbacks) == 0 {
+		fallbacks = append(fallbacks, model.DEFAULT_LOCALE)
+	}
+
+	return fallbacks
+}
+
+// findLocale returns the name of the loaded locale that matches the given one, regardless of case or whether it's
+// written with an underscore, or an empty string if there isn't one.
+func findLocale(locale string) string {
+	if _, ok := locales[locale]; ok {
+		return locale
+	}
+
+	normalized := strings.ToLower(strings.Replace(locale, "_", "-", -1))
+	for name := range locales {
+		if strings.ToLower(name) == normalized {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// MissingTranslations compares each of the loaded locales other than English to English, returning the ids of the
+// messages that each of them hasn't translated, sorted and by locale. Locales that have translated everything are
+// left out.
+func MissingTranslations() (map[string][]string, error) {
+	translated := make(map[string]map[string]bool, len(locales))
+	for locale, path := range locales {
+		ids, err := readTranslatedIds(path)
+		if err != nil {
+			return nil, err
+		}
+		translated[locale] = ids
+	}
+
+	english, ok := translated[model.DEFAULT_LOCALE]
+	if !ok {
+		return nil, fmt.Errorf("Failed to find translations for '%v'", model.DEFAULT_LOCALE)
+	}
+
+	missing := make(map[string][]string)
+	for locale, ids := range translated {
+		if locale == model.DEFAULT_LOCALE {
+			continue
+		}
+
+		for id := range english {
+			if !ids[id] {
+				missing[locale] = append(missing[locale], id)
+			}
+		}
+
+		if len(missing[locale]) > 0 {
+			sort.Strings(missing[locale])
+		}
 	}
+
+	return missing, nil
+}
+
+// readTranslatedIds returns the ids of the messages in a translation file that have been translated.
+func readTranslatedIds(path string) (map[string]bool, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []struct {
+		Id          string      `json:"id"`
+		Translation interface{} `json:"translation"`
+	}
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return nil, fmt.Errorf("Failed to read translations from '%v': %v", path, err.Error())
+	}
+
+	ids := make(map[string]bool, len(messages))
+	for _, message := range messages {
+		// Messages with plural forms have a translation for each of them
+		switch translation := message.Translation.(type) {
+		case string:
+			ids[message.Id] = translation != ""
+		case map[string]interface{}:
+			ids[message.Id] = len(translation) > 0
+		}
+	}
+
+	return ids, nil
 }
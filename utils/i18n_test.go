@@ -0,0 +1,72 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nicksnyder/go-i18n/i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestTranslationFallbacks(t *testing.T) {
+	require.Nil(t, TranslationsPreInit())
+
+	// Only translated into English
+	id := "app.reaction.save_reaction.disabled.app_error"
+	english := T(id)
+	require.NotEqual(t, id, english)
+
+	t.Run("variant falls back to English", func(t *testing.T) {
+		missing, err := MissingTranslations()
+		require.Nil(t, err)
+		require.Contains(t, missing["pt-BR"], id)
+		assert.NotContains(t, missing, model.DEFAULT_LOCALE)
+
+		assert.Equal(t, english, GetUserTranslations("pt-BR")(id))
+		assert.Equal(t, "Abril", GetUserTranslations("pt-BR")("April"))
+
+		appErr := model.NewAppError("TestTranslationFallbacks", id, nil, "", 0)
+		appErr.Translate(GetUserTranslations("pt-BR"))
+		assert.Equal(t, english, appErr.Message)
+	})
+
+	t.Run("variant falls back to language", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		// A locale for the language that translates only the one message
+		path := filepath.Join(dir, "pt.json")
+		require.NoError(t, ioutil.WriteFile(path, []byte(`[{"id": "`+id+`", "translation": "Reações desativadas"}]`), 0600))
+		require.NoError(t, i18n.LoadTranslationFile(path))
+		locales["pt"] = path
+		defer delete(locales, "pt")
+
+		assert.Equal(t, []string{"pt-BR", "pt", model.DEFAULT_LOCALE}, localeFallbacks("pt_br"))
+		assert.Equal(t, []string{"pt", model.DEFAULT_LOCALE}, localeFallbacks("pt-PT"))
+
+		assert.Equal(t, "Reações desativadas", GetUserTranslations("pt-BR")(id))
+		assert.Equal(t, "Reações desativadas", GetUserTranslations("pt-PT")(id))
+		assert.Equal(t, "Abril", GetUserTranslations("pt-BR")("April"))
+		assert.Equal(t, "April", GetUserTranslations("pt-PT")("April"), "messages that the language doesn't have should be in English")
+
+		missing, err := MissingTranslations()
+		require.Nil(t, err)
+		assert.NotContains(t, missing["pt"], id)
+		assert.Contains(t, missing["pt"], "April")
+	})
+
+	t.Run("unknown locale", func(t *testing.T) {
+		assert.Equal(t, []string{model.DEFAULT_LOCALE}, localeFallbacks("xx-YY"))
+		assert.Equal(t, english, GetUserTranslations("xx-YY")(id))
+		assert.Equal(t, "not.a.translation.id", GetUserTranslations("pt-BR")("not.a.translation.id"))
+	})
+}
@@ -11,6 +11,8 @@ import (
 	"container/list"
 	"sync"
 	"time"
+
+	"github.com/mattermost/mattermost-server/einterfaces"
 )
 
 // Caching Interface
@@ -36,6 +38,9 @@ type Cache struct {
 	invalidateClusterEvent string
 	currentGeneration      int64
 	len                    int
+
+	// metrics counts hits, misses and evictions by the cache's name when it's set.
+	metrics einterfaces.MetricsInterface
 }
 
 // entry is used to hold a value in the evictList
@@ -109,6 +114,10 @@ func (c *Cache) AddWithExpiresInSecs(key, value interface{}, expireAtSecs int64)
 
 	if c.evictList.Len() > c.size {
 		c.removeElement(c.evictList.Back())
+
+		if c.metrics != nil {
+			c.metrics.IncrementMemCacheEvictionCounter(c.name)
+		}
 	}
 }
 
@@ -121,16 +130,27 @@ func (c *Cache) Get(key interface{}) (value interface{}, ok bool) {
 
 		if e.generation != c.currentGeneration || (e.expireAtSecs > 0 && (time.Now().UnixNano()/int64(time.Second)) > e.expireAtSecs) {
 			c.removeElement(ent)
+			c.incrementMissCounter()
 			return nil, false
 		}
 
 		c.evictList.MoveToFront(ent)
+		if c.metrics != nil {
+			c.metrics.IncrementMemCacheHitCounter(c.name)
+		}
 		return ent.Value.(*entry).value, true
 	}
 
+	c.incrementMissCounter()
 	return nil, false
 }
 
+func (c *Cache) incrementMissCounter() {
+	if c.metrics != nil {
+		c.metrics.IncrementMemCacheMissCounter(c.name)
+	}
+}
+
 func (c *Cache) Remove(key interface{}) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
@@ -173,6 +193,16 @@ func (c *Cache) GetInvalidateClusterEvent() string {
 	return c.invalidateClusterEvent
 }
 
+// Size returns the maximum number of items that the cache holds.
+func (c *Cache) Size() int {
+	return c.size
+}
+
+// DefaultExpiry returns the number of seconds that items added with AddWithDefaultExpires are kept for.
+func (c *Cache) DefaultExpiry() int64 {
+	return c.defaultExpiry
+}
+
 // removeElement is used to remove a given list element from the cache
 func (c *Cache) removeElement(e *list.Element) {
 	c.evictList.Remove(e)
@@ -0,0 +1,164 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package markdown
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Autolink is a URL in plain text that's turned into a link in the same way as GitHub Flavored Markdown's extended
+// autolinks. Links are recognized if they start with http://, https://, ftp:// or www. followed by a domain.
+type Autolink struct {
+	inlineBase
+
+	RawDestination Range
+
+	markdown string
+}
+
+// Text returns the link as it's written in the markdown.
+func (i *Autolink) Text() string {
+	return Unescape(i.markdown[i.RawDestination.Position:i.RawDestination.End])
+}
+
+// Destination returns where the link goes. Links that start with www. go to http.
+func (i *Autolink) Destination() string {
+	destination := i.Text()
+	if hasPrefixFold(destination, "www.") {
+		destination = "http://" + destination
+	}
+	return destination
+}
+
+var autolinkPrefixes = []string{"http://", "https://", "ftp://", "www."}
+
+// isAutolinkBoundary returns whether an autolink can start right after the given byte, which it can at the
+// beginning of a line, after whitespace, or after one of the delimiters that can be put around it.
+func isAutolinkBoundary(c byte) bool {
+	return isWhitespaceByte(c) || strings.IndexByte("*_~(<", c) != -1
+}
+
+// findAutolink returns the start and end of the first autolink in markdown that starts between start and end.
+func findAutolink(markdown string, start, end int) (linkStart, linkEnd int, ok bool) {
+	for i := start; i < end; i++ {
+		if i > 0 && !isAutolinkBoundary(markdown[i-1]) {
+			continue
+		}
+
+		if linkEnd, ok := parseAutolink(markdown, i); ok {
+			return i, linkEnd, true
+		}
+	}
+
+	return 0, 0, false
+}
+
+// parseAutolink returns the end of the autolink that starts at position in markdown, if there's one there.
+func parseAutolink(markdown string, position int) (end int, ok bool) {
+	domainStart := -1
+	for _, prefix := range autolinkPrefixes {
+		if hasPrefixFold(markdown[position:], prefix) {
+			domainStart = position + len(prefix)
+			if prefix == "www." {
+				domainStart = position
+			}
+			break
+		}
+	}
+	if domainStart == -1 {
+		return 0, false
+	}
+
+	// A link ends at whitespace or at anything that would start other markdown
+	end = position
+	for end < len(markdown) {
+		c, size := utf8.DecodeRuneInString(markdown[end:])
+		if isWhitespace(c) || strings.ContainsRune("<>[]`", c) {
+			break
+		}
+		end += size
+	}
+
+	end = trimAutolink(markdown, position, end)
+
+	domainEnd := domainStart
+	for domainEnd < end {
+		c, size := utf8.DecodeRuneInString(markdown[domainEnd:])
+		if !isAutolinkDomainRune(c) {
+			break
+		}
+		domainEnd += size
+	}
+
+	if !isValidAutolinkDomain(markdown[domainStart:domainEnd]) {
+		return 0, false
+	}
+
+	return end, true
+}
+
+// trimAutolink returns where an autolink really ends by leaving out any trailing punctuation, closing parentheses
+// that don't have a matching opening one in the link, and entity references.
+func trimAutolink(markdown string, start, end int) int {
+	for end > start {
+		switch c := markdown[end-1]; {
+		case strings.IndexByte("?!.,:*_~'\"", c) != -1:
+			end--
+		case c == ')' && strings.Count(markdown[start:end], ")") > strings.Count(markdown[start:end], "("):
+			end--
+		case c == ';':
+			ampersand := strings.LastIndexByte(markdown[start:end-1], '&')
+			if ampersand == -1 || !isAlphanumeric(markdown[start+ampersand+1:end-1]) {
+				return end
+			}
+			end = start + ampersand
+		default:
+			return end
+		}
+	}
+
+	return end
+}
+
+func isAutolinkDomainRune(c rune) bool {
+	return c == '.' || c == '-' || c == '_' || unicode.IsLetter(c) || unicode.IsNumber(c) || unicode.IsMark(c)
+}
+
+// isValidAutolinkDomain returns whether a domain can be linked. It must have at least two labels, and the last two
+// can't have underscores in them.
+func isValidAutolinkDomain(domain string) bool {
+	labels := strings.Split(strings.TrimRight(domain, "."), ".")
+	if len(labels) < 2 {
+		return false
+	}
+
+	for i, label := range labels {
+		if label == "" || (i >= len(labels)-2 && strings.Contains(label, "_")) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isAlphanumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutolinks(t *testing.T) {
+	// Most of these come from the extended autolinks section of the GitHub Flavored Markdown spec
+	for name, tc := range map[string]struct {
+		Markdown     string
+		ExpectedHTML string
+	}{
+		"www": {
+			Markdown:     "www.commonmark.org",
+			ExpectedHTML: `<p><a href="http://www.commonmark.org">www.commonmark.org</a></p>`,
+		},
+		"www with a path": {
+			Markdown:     "Visit www.commonmark.org/help for more information.",
+			ExpectedHTML: `<p>Visit <a href="http://www.commonmark.org/help">www.commonmark.org/help</a> for more information.</p>`,
+		},
+		"trailing punctuation": {
+			Markdown:     "Visit www.commonmark.org/a.b.",
+			ExpectedHTML: `<p>Visit <a href="http://www.commonmark.org/a.b">www.commonmark.org/a.b</a>.</p>`,
+		},
+		"balanced parentheses": {
+			Markdown:     "(www.google.com/search?q=Markup+(business))",
+			ExpectedHTML: `<p>(<a href="http://www.google.com/search?q=Markup+(business)">www.google.com/search?q=Markup+(business)</a>)</p>`,
+		},
+		"unbalanced parentheses": {
+			Markdown:     "www.google.com/search?q=Markup+(business)))",
+			ExpectedHTML: `<p><a href="http://www.google.com/search?q=Markup+(business)">www.google.com/search?q=Markup+(business)</a>))</p>`,
+		},
+		"query": {
+			Markdown:     "www.google.com/search?q=commonmark&hl=en",
+			ExpectedHTML: `<p><a href="http://www.google.com/search?q=commonmark&amp;hl=en">www.google.com/search?q=commonmark&amp;hl=en</a></p>`,
+		},
+		"entity reference": {
+			Markdown:     "www.google.com/search?q=commonmark&hl;",
+			ExpectedHTML: `<p><a href="http://www.google.com/search?q=commonmark">www.google.com/search?q=commonmark</a>&amp;hl;</p>`,
+		},
+		"less than": {
+			Markdown:     "www.commonmark.org/he<lp",
+			ExpectedHTML: `<p><a href="http://www.commonmark.org/he">www.commonmark.org/he</a>&lt;lp</p>`,
+		},
+		"schemes": {
+			Markdown:     "http://commonmark.org\n\n(Visit https://encrypted.google.com/search?q=Markup+(business))\n\nAnonymous FTP is available at ftp://foo.bar.baz.",
+			ExpectedHTML: `<p><a href="http://commonmark.org">http://commonmark.org</a></p><p>(Visit <a href="https://encrypted.google.com/search?q=Markup+(business)">https://encrypted.google.com/search?q=Markup+(business)</a>)</p><p>Anonymous FTP is available at <a href="ftp://foo.bar.baz">ftp://foo.bar.baz</a>.</p>`,
+		},
+		"invalid domain": {
+			Markdown:     "http://localhost and www.foo_bar.com are not links",
+			ExpectedHTML: `<p>http://localhost and www.foo_bar.com are not links</p>`,
+		},
+		"in the middle of a word": {
+			Markdown:     "xhttp://example.com",
+			ExpectedHTML: `<p>xhttp://example.com</p>`,
+		},
+		"internationalized domain": {
+			Markdown:     "https://例子.测试/path",
+			ExpectedHTML: `<p><a href="https://%E4%BE%8B%E5%AD%90.%E6%B5%8B%E8%AF%95/path">https://例子.测试/path</a></p>`,
+		},
+		"in a link": {
+			Markdown:     "[see http://example.com](http://example.org)",
+			ExpectedHTML: `<p><a href="http://example.org">see <a href="http://example.com">http://example.com</a></a></p>`,
+		},
+		"in code": {
+			Markdown:     "`http://example.com`",
+			ExpectedHTML: `<p><code>http://example.com</code></p>`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.ExpectedHTML, RenderHTML(tc.Markdown))
+		})
+	}
+}
+
+func TestLinkRanges(t *testing.T) {
+	markdown := "> see [the *docs*](http://example.com) and ![image][ref]\n> or www.example.com\n\n[ref]: http://example.com/image.png"
+
+	var ranges []string
+	Inspect(markdown, func(blockOrInline interface{}) bool {
+		switch v := blockOrInline.(type) {
+		case *InlineLink:
+			ranges = append(ranges, markdown[v.Range.Position:v.Range.End], markdown[v.RawLabel.Position:v.RawLabel.End])
+		case *ReferenceImage:
+			ranges = append(ranges, markdown[v.Range.Position:v.Range.End], markdown[v.RawLabel.Position:v.RawLabel.End])
+		case *Autolink:
+			ranges = append(ranges, markdown[v.RawDestination.Position:v.RawDestination.End])
+		}
+		return true
+	})
+
+	assert.Equal(t, []string{
+		"[the *docs*](http://example.com)",
+		"the *docs*",
+		"![image][ref]",
+		"image",
+		"www.example.com",
+	}, ranges)
+}
@@ -23,15 +23,62 @@ func RenderHTML(markdown string) string {
 	return RenderBlockHTML(Parse(markdown))
 }
 
+// RenderHTMLWithText is like RenderHTML except that renderText is called to produce the HTML for each run of plain
+// text instead of it being escaped, which allows callers to turn things like mentions into markup of their own. The
+// text given to renderText has already been unescaped, so it's up to renderText to escape it again.
+//
+// Since the output is meant to be shown to people, links and images are only kept if their URLs are relative or
+// use a scheme from safeURLSchemes, so that something like a javascript: link can't run in the page.
+func RenderHTMLWithText(markdown string, renderText func(text string) string) string {
+	document, referenceDefinitions := Parse(markdown)
+	r := &htmlRenderer{referenceDefinitions: referenceDefinitions, renderText: renderText, safeURLsOnly: true}
+	return r.renderBlock(document, false)
+}
+
+// RenderSafeHTML is like RenderHTML except that it only keeps links and images with safe URLs like
+// RenderHTMLWithText, so its output can be shown to people.
+func RenderSafeHTML(markdown string) string {
+	return RenderHTMLWithText(markdown, htmlEscaper.Replace)
+}
+
+type htmlRenderer struct {
+	referenceDefinitions []*ReferenceDefinition
+	renderText           func(text string) string
+
+	// safeURLsOnly renders the text of links and images with unsafe URLs instead of the links and images themselves
+	safeURLsOnly bool
+}
+
+var safeURLSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+}
+
+// isSafeURL returns true if the URL is relative or uses one of the safeURLSchemes.
+func isSafeURL(url string) bool {
+	end := strings.IndexAny(url, ":/?#")
+	if end == -1 || url[end] != ':' {
+		return true
+	}
+
+	return safeURLSchemes[strings.ToLower(url[:end])]
+}
+
+func (r *htmlRenderer) isAllowedURL(url string) bool {
+	return !r.safeURLsOnly || isSafeURL(url)
+}
+
 func RenderBlockHTML(block Block, referenceDefinitions []*ReferenceDefinition) (result string) {
-	return renderBlockHTML(block, referenceDefinitions, false)
+	r := &htmlRenderer{referenceDefinitions: referenceDefinitions, renderText: htmlEscaper.Replace}
+	return r.renderBlock(block, false)
 }
 
-func renderBlockHTML(block Block, referenceDefinitions []*ReferenceDefinition, isTightList bool) (result string) {
+func (r *htmlRenderer) renderBlock(block Block, isTightList bool) (result string) {
 	switch v := block.(type) {
 	case *Document:
 		for _, block := range v.Children {
-			result += RenderBlockHTML(block, referenceDefinitions)
+			result += r.renderBlock(block, false)
 		}
 	case *Paragraph:
 		if len(v.Text) == 0 {
@@ -40,8 +87,8 @@ func renderBlockHTML(block Block, referenceDefinitions []*ReferenceDefinition, i
 		if !isTightList {
 			result += "<p>"
 		}
-		for _, inline := range v.ParseInlines(referenceDefinitions) {
-			result += RenderInlineHTML(inline)
+		for _, inline := range v.ParseInlines(r.referenceDefinitions) {
+			result += r.renderInline(inline)
 		}
 		if !isTightList {
 			result += "</p>"
@@ -57,7 +104,7 @@ func renderBlockHTML(block Block, referenceDefinitions []*ReferenceDefinition, i
 			result += "<ul>"
 		}
 		for _, block := range v.Children {
-			result += renderBlockHTML(block, referenceDefinitions, !v.IsLoose)
+			result += r.renderBlock(block, !v.IsLoose)
 		}
 		if v.IsOrdered {
 			result += "</ol>"
@@ -67,13 +114,13 @@ func renderBlockHTML(block Block, referenceDefinitions []*ReferenceDefinition, i
 	case *ListItem:
 		result += "<li>"
 		for _, block := range v.Children {
-			result += renderBlockHTML(block, referenceDefinitions, isTightList)
+			result += r.renderBlock(block, isTightList)
 		}
 		result += "</li>"
 	case *BlockQuote:
 		result += "<blockquote>"
 		for _, block := range v.Children {
-			result += RenderBlockHTML(block, referenceDefinitions)
+			result += r.renderBlock(block, false)
 		}
 		result += "</blockquote>"
 	case *FencedCode:
@@ -115,9 +162,14 @@ func escapeURL(url string) (result string) {
 }
 
 func RenderInlineHTML(inline Inline) (result string) {
+	r := &htmlRenderer{renderText: htmlEscaper.Replace}
+	return r.renderInline(inline)
+}
+
+func (r *htmlRenderer) renderInline(inline Inline) (result string) {
 	switch v := inline.(type) {
 	case *Text:
-		return htmlEscaper.Replace(v.Text)
+		return r.renderText(v.Text)
 	case *HardLineBreak:
 		return "<br />"
 	case *SoftLineBreak:
@@ -125,37 +177,60 @@ func RenderInlineHTML(inline Inline) (result string) {
 	case *CodeSpan:
 		return "<code>" + htmlEscaper.Replace(v.Code) + "</code>"
 	case *InlineImage:
+		if !r.isAllowedURL(v.Destination()) {
+			return htmlEscaper.Replace(renderImageAltText(v.Children))
+		}
 		result += `<img src="` + htmlEscaper.Replace(escapeURL(v.Destination())) + `" alt="` + htmlEscaper.Replace(renderImageAltText(v.Children)) + `"`
 		if title := v.Title(); title != "" {
 			result += ` title="` + htmlEscaper.Replace(title) + `"`
 		}
 		result += ` />`
 	case *ReferenceImage:
+		if !r.isAllowedURL(v.Destination()) {
+			return htmlEscaper.Replace(renderImageAltText(v.Children))
+		}
 		result += `<img src="` + htmlEscaper.Replace(escapeURL(v.Destination())) + `" alt="` + htmlEscaper.Replace(renderImageAltText(v.Children)) + `"`
 		if title := v.Title(); title != "" {
 			result += ` title="` + htmlEscaper.Replace(title) + `"`
 		}
 		result += ` />`
 	case *InlineLink:
+		if !r.isAllowedURL(v.Destination()) {
+			for _, inline := range v.Children {
+				result += r.renderInline(inline)
+			}
+			return
+		}
 		result += `<a href="` + htmlEscaper.Replace(escapeURL(v.Destination())) + `"`
 		if title := v.Title(); title != "" {
 			result += ` title="` + htmlEscaper.Replace(title) + `"`
 		}
 		result += `>`
 		for _, inline := range v.Children {
-			result += RenderInlineHTML(inline)
+			result += r.renderInline(inline)
 		}
 		result += "</a>"
 	case *ReferenceLink:
+		if !r.isAllowedURL(v.Destination()) {
+			for _, inline := range v.Children {
+				result += r.renderInline(inline)
+			}
+			return
+		}
 		result += `<a href="` + htmlEscaper.Replace(escapeURL(v.Destination())) + `"`
 		if title := v.Title(); title != "" {
 			result += ` title="` + htmlEscaper.Replace(title) + `"`
 		}
 		result += `>`
 		for _, inline := range v.Children {
-			result += RenderInlineHTML(inline)
+			result += r.renderInline(inline)
 		}
 		result += "</a>"
+	case *Autolink:
+		if !r.isAllowedURL(v.Destination()) {
+			return htmlEscaper.Replace(v.Text())
+		}
+		result += `<a href="` + htmlEscaper.Replace(escapeURL(v.Destination())) + `">` + htmlEscaper.Replace(v.Text()) + "</a>"
 	default:
 		panic(fmt.Sprintf("missing case for type %T", v))
 	}
@@ -173,6 +248,8 @@ func renderImageChildAltText(inline Inline) (result string) {
 	switch v := inline.(type) {
 	case *Text:
 		return v.Text
+	case *Autolink:
+		return v.Text()
 	case *InlineImage:
 		for _, inline := range v.Children {
 			result += renderImageChildAltText(inline)
@@ -0,0 +1,81 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderHTMLWithText(t *testing.T) {
+	renderText := func(text string) string {
+		return strings.Replace(htmlEscaper.Replace(text), "@user", `<span class="mention">@user</span>`, -1)
+	}
+
+	for name, tc := range map[string]struct {
+		Markdown     string
+		ExpectedHTML string
+	}{
+		"text": {
+			Markdown:     "hello @user & friends",
+			ExpectedHTML: `<p>hello <span class="mention">@user</span> &amp; friends</p>`,
+		},
+		"escaped text": {
+			Markdown:     `\<b>@user\</b>`,
+			ExpectedHTML: `<p>&lt;b&gt;<span class="mention">@user</span>&lt;/b&gt;</p>`,
+		},
+		"code": {
+			Markdown:     "`@user` and\n\n    @user",
+			ExpectedHTML: `<p><code>@user</code> and</p><pre><code>@user</code></pre>`,
+		},
+		"link": {
+			Markdown:     "[@user](http://example.com/@user)",
+			ExpectedHTML: `<p><a href="http://example.com/@user"><span class="mention">@user</span></a></p>`,
+		},
+		"relative link": {
+			Markdown:     "[@user](/team/messages/@user)",
+			ExpectedHTML: `<p><a href="/team/messages/@user"><span class="mention">@user</span></a></p>`,
+		},
+		"mailto link": {
+			Markdown:     "[mail](mailto:user@example.com)",
+			ExpectedHTML: `<p><a href="mailto:user@example.com">mail</a></p>`,
+		},
+		"javascript link": {
+			Markdown:     "[x](javascript:alert(1))",
+			ExpectedHTML: `<p>x</p>`,
+		},
+		"javascript link with mixed case": {
+			Markdown:     "[x](JavaScript:alert(1))",
+			ExpectedHTML: `<p>x</p>`,
+		},
+		"javascript reference link": {
+			Markdown:     "[x]\n\n[x]: javascript:alert(1)",
+			ExpectedHTML: `<p>x</p>`,
+		},
+		"data image": {
+			Markdown:     "![alt](data:text/html;base64,PHNjcmlwdD4=)",
+			ExpectedHTML: `<p>alt</p>`,
+		},
+		"image": {
+			Markdown:     "![alt](https://example.com/a.png)",
+			ExpectedHTML: `<p><img src="https://example.com/a.png" alt="alt" /></p>`,
+		},
+		"list": {
+			Markdown:     "- @user\n- other",
+			ExpectedHTML: `<ul><li><span class="mention">@user</span></li><li>other</li></ul>`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.ExpectedHTML, RenderHTMLWithText(tc.Markdown, renderText))
+		})
+	}
+}
+
+func TestRenderSafeHTML(t *testing.T) {
+	assert.Equal(t, "<p><code>code</code> &lt;script&gt;</p>", RenderSafeHTML("`code` <script>"))
+	assert.Equal(t, `<p><a href="https://example.com">x</a></p>`, RenderSafeHTML("[x](https://example.com)"))
+	assert.Equal(t, `<p>x</p>`, RenderSafeHTML("[x](javascript:alert(1))"))
+}
@@ -43,6 +43,10 @@ type InlineLinkOrImage struct {
 
 	Children []Inline
 
+	// Range is where the whole link or image is in the markdown, and RawLabel is where the text between its
+	// brackets is.
+	Range          Range
+	RawLabel       Range
 	RawDestination Range
 
 	markdown string
@@ -70,6 +74,11 @@ type ReferenceLinkOrImage struct {
 	*ReferenceDefinition
 
 	Children []Inline
+
+	// Range is where the whole link or image is in the markdown, not including its reference definition, and
+	// RawLabel is where the text between its first brackets is.
+	Range    Range
+	RawLabel Range
 }
 
 type ReferenceLink struct {
@@ -175,23 +184,36 @@ func (p *inlineParser) parseEscapeCharacter() {
 }
 
 func (p *inlineParser) parseText() {
-	if next := strings.IndexAny(p.raw[p.position:], "\r\n\\`&![]"); next == -1 {
-		p.inlines = append(p.inlines, &Text{
-			Text: strings.TrimRightFunc(p.raw[p.position:], isWhitespace),
-		})
-		p.position = len(p.raw)
-	} else {
-		if p.raw[p.position+next] == '\r' || p.raw[p.position+next] == '\n' {
-			p.inlines = append(p.inlines, &Text{
-				Text: strings.TrimRightFunc(p.raw[p.position:p.position+next], isWhitespace),
-			})
-		} else {
+	end := len(p.raw)
+	if next := strings.IndexAny(p.raw[p.position:], "\r\n\\`&![]"); next != -1 {
+		end = p.position + next
+	}
+
+	if start, linkEnd, ok := findAutolink(p.raw, p.position, end); ok {
+		if start > p.position {
 			p.inlines = append(p.inlines, &Text{
-				Text: p.raw[p.position : p.position+next],
+				Text: p.raw[p.position:start],
 			})
 		}
-		p.position += next
+		position := relativeToAbsolutePosition(p.ranges, start)
+		p.inlines = append(p.inlines, &Autolink{
+			RawDestination: Range{position, position + linkEnd - start},
+			markdown:       p.markdown,
+		})
+		p.position = linkEnd
+		return
 	}
+
+	if end == len(p.raw) || p.raw[end] == '\r' || p.raw[end] == '\n' {
+		p.inlines = append(p.inlines, &Text{
+			Text: strings.TrimRightFunc(p.raw[p.position:end], isWhitespace),
+		})
+	} else {
+		p.inlines = append(p.inlines, &Text{
+			Text: p.raw[p.position:end],
+		})
+	}
+	p.position = end
 }
 
 func (p *inlineParser) parseLinkOrImageDelimiter() {
@@ -265,6 +287,15 @@ func (p *inlineParser) peekAtInlineLinkDestinationAndTitle(position int) (destin
 	return destination, title, closingPosition + 1, true
 }
 
+// absoluteRange returns the range of the markdown that goes from start up to end in the paragraph's raw text.
+func (p *inlineParser) absoluteRange(start, end int) Range {
+	if end <= start {
+		position := relativeToAbsolutePosition(p.ranges, start)
+		return Range{position, position}
+	}
+	return Range{relativeToAbsolutePosition(p.ranges, start), relativeToAbsolutePosition(p.ranges, end-1) + 1}
+}
+
 func (p *inlineParser) referenceDefinition(label string) *ReferenceDefinition {
 	clean := strings.Join(strings.Fields(label), " ")
 	for _, d := range p.referenceDefinitions {
@@ -292,6 +323,8 @@ func (p *inlineParser) lookForLinkOrImage() {
 			destinationMarkdownPosition := relativeToAbsolutePosition(p.ranges, destination.Position)
 			linkOrImage := InlineLinkOrImage{
 				Children:       append([]Inline(nil), p.inlines[d.TextNode+1:]...),
+				Range:          p.absoluteRange(d.Range.Position, next),
+				RawLabel:       p.absoluteRange(d.Range.End, p.position),
 				RawDestination: Range{destinationMarkdownPosition, destinationMarkdownPosition + destination.End - destination.Position},
 				markdown:       p.markdown,
 				rawTitle:       p.raw[title.Position:title.End],
@@ -318,6 +351,8 @@ func (p *inlineParser) lookForLinkOrImage() {
 					linkOrImage := ReferenceLinkOrImage{
 						ReferenceDefinition: reference,
 						Children:            append([]Inline(nil), p.inlines[d.TextNode+1:]...),
+						Range:               p.absoluteRange(d.Range.Position, next),
+						RawLabel:            p.absoluteRange(d.Range.End, p.position),
 					}
 					if d.Type == imageOpeningDelimiter {
 						inline = &ReferenceImage{linkOrImage}
@@ -0,0 +1,161 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+// Package tracing times the calls made while handling a sampled API request. Requests aren't passed a context that
+// could carry their trace through the app and the store, so a trace is tied to the goroutine handling its request
+// instead, and calls made from other goroutines aren't included in it.
+package tracing
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+var (
+	// active is how many traces are in progress, so that calls made while there are none can skip looking for one
+	active int32
+
+	traces     = map[uint64]*Trace{}
+	tracesLock sync.RWMutex
+)
+
+type Trace struct {
+	record      *model.RequestTrace
+	start       time.Time
+	goroutineId uint64
+	finished    bool
+	lock        sync.Mutex
+}
+
+// Start begins tracing the calls made by the current goroutine into record.
+func Start(record *model.RequestTrace) *Trace {
+	t := &Trace{
+		record:      record,
+		start:       time.Now(),
+		goroutineId: goroutineId(),
+	}
+	t.record.StartAt = model.GetMillis()
+
+	tracesLock.Lock()
+	traces[t.goroutineId] = t
+	tracesLock.Unlock()
+	atomic.AddInt32(&active, 1)
+
+	return t
+}
+
+// Finish stops the trace and returns its record. Calls that are still running when it's finished are left out.
+func (t *Trace) Finish() *model.RequestTrace {
+	tracesLock.Lock()
+	delete(traces, t.goroutineId)
+	tracesLock.Unlock()
+	atomic.AddInt32(&active, -1)
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.finished = true
+	t.record.DurationMillis = millisSince(t.start)
+
+	return t.record
+}
+
+// Active returns true if any request is being traced. It's cheap enough to call before working out the name of a
+// span so that nothing else is done for every call while tracing is off.
+func Active() bool {
+	return atomic.LoadInt32(&active) > 0
+}
+
+// StartSpan begins timing a call of the given kind if the current goroutine is being traced, returning a function to
+// call when it's done. The function can be called from another goroutine.
+func StartSpan(kind string, name string) func() {
+	if !Active() {
+		return noop
+	}
+
+	tracesLock.RLock()
+	t := traces[goroutineId()]
+	tracesLock.RUnlock()
+
+	if t == nil {
+		return noop
+	}
+
+	start := time.Now()
+	return func() {
+		t.addSpan(&model.RequestTraceSpan{
+			Kind:           kind,
+			Name:           name,
+			StartMillis:    float64(start.Sub(t.start)) / float64(time.Millisecond),
+			DurationMillis: millisSince(start),
+		})
+	}
+}
+
+func (t *Trace) addSpan(span *model.RequestTraceSpan) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.finished {
+		return
+	}
+
+	if len(t.record.Spans) >= model.REQUEST_TRACE_MAX_SPANS {
+		t.record.DroppedSpans++
+		return
+	}
+
+	t.record.Spans = append(t.record.Spans, span)
+}
+
+func noop() {}
+
+func millisSince(start time.Time) float64 {
+	return float64(time.Since(start)) / float64(time.Millisecond)
+}
+
+// goroutineId returns the id of the current goroutine, which the runtime only gives out at the start of its stack.
+func goroutineId() uint64 {
+	var buf [64]byte
+	stack := bytes.TrimPrefix(buf[:runtime.Stack(buf[:], false)], []byte("goroutine "))
+
+	if i := bytes.IndexByte(stack, ' '); i > 0 {
+		id, _ := strconv.ParseUint(string(stack[:i]), 10, 64)
+		return id
+	}
+
+	return 0
+}
+
+// CallerName returns the name of the function that's skip calls up the stack from the one calling CallerName, so
+// that spans can be named after what made them. CallerName(1) returns the name of the caller's caller.
+func CallerName(skip int) string {
+	pc, _, _, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return ""
+	}
+
+	return FuncName(runtime.FuncForPC(pc))
+}
+
+// FuncName returns the name of the function without the path of its package, such as
+// "sqlstore.SqlChannelStore.Get".
+func FuncName(f *runtime.Func) string {
+	if f == nil {
+		return ""
+	}
+
+	name := f.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	return strings.NewReplacer("(*", "", ")", "").Replace(name)
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package tracing
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestTrace(t *testing.T) {
+	t.Run("spans are recorded for the traced goroutine", func(t *testing.T) {
+		trace := Start(&model.RequestTrace{Handler: "handler"})
+		require.True(t, Active())
+
+		StartSpan(model.REQUEST_TRACE_SPAN_STORE, "SqlChannelStore.Get")()
+
+		// spans can be ended from another goroutine, like store calls are
+		endSpan := StartSpan(model.REQUEST_TRACE_SPAN_FILESTORE, "ReadFile")
+		done := make(chan bool)
+		go func() {
+			endSpan()
+
+			// but ones started from another goroutine aren't part of the trace
+			StartSpan(model.REQUEST_TRACE_SPAN_STORE, "SqlPostStore.Get")()
+			close(done)
+		}()
+		<-done
+
+		record := trace.Finish()
+		assert.False(t, Active())
+		assert.Equal(t, "handler", record.Handler)
+		assert.NotZero(t, record.StartAt)
+		if assert.Len(t, record.Spans, 2) {
+			assert.Equal(t, model.REQUEST_TRACE_SPAN_STORE, record.Spans[0].Kind)
+			assert.Equal(t, "SqlChannelStore.Get", record.Spans[0].Name)
+			assert.Equal(t, model.REQUEST_TRACE_SPAN_FILESTORE, record.Spans[1].Kind)
+		}
+
+		// spans that end after the trace is finished are left out
+		endSpan = StartSpan(model.REQUEST_TRACE_SPAN_STORE, "SqlChannelStore.Get")
+		endSpan()
+		assert.Len(t, record.Spans, 2)
+	})
+
+	t.Run("nothing is recorded while no requests are traced", func(t *testing.T) {
+		require.False(t, Active())
+
+		record := &model.RequestTrace{}
+		Start(record).Finish()
+		StartSpan(model.REQUEST_TRACE_SPAN_STORE, "SqlChannelStore.Get")()
+
+		assert.Empty(t, record.Spans)
+	})
+
+	t.Run("the number of spans is limited", func(t *testing.T) {
+		trace := Start(&model.RequestTrace{})
+		for i := 0; i < model.REQUEST_TRACE_MAX_SPANS+10; i++ {
+			StartSpan(model.REQUEST_TRACE_SPAN_STORE, "SqlChannelStore.Get")()
+		}
+
+		record := trace.Finish()
+		assert.Len(t, record.Spans, model.REQUEST_TRACE_MAX_SPANS)
+		assert.Equal(t, 10, record.DroppedSpans)
+	})
+}
+
+func TestFuncName(t *testing.T) {
+	pc, _, _, _ := runtime.Caller(0)
+	assert.Equal(t, "tracing.TestFuncName", FuncName(runtime.FuncForPC(pc)))
+	assert.Equal(t, "tracing.Trace.Finish", FuncName(runtime.FuncForPC(reflect.ValueOf((*Trace).Finish).Pointer())))
+	assert.Equal(t, "", FuncName(nil))
+}
+
+func TestCallerName(t *testing.T) {
+	assert.Equal(t, "tracing.TestCallerName", callerNameOf())
+}
+
+func callerNameOf() string {
+	return CallerName(1)
+}
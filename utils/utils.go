@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"regexp"
 	"strings"
 
 	"github.com/mattermost/mattermost-server/model"
@@ -64,28 +65,83 @@ func RemoveDuplicatesFromStringArray(arr []string) []string {
 	return result
 }
 
-func GetIpAddress(r *http.Request) string {
-	address := ""
+// GetIpAddress returns the address of the client that made the request. The trustedProxyIPHeader, such as
+// X-Forwarded-For, is only read when the request comes from one of the space separated trustedProxyCIDRs since
+// anyone else could have set it to anything. Its addresses are walked back from the most recent hop, skipping over
+// other trusted proxies, so that any that the client added itself are ignored. A trusted proxy that doesn't set the
+// header can pass the address along in X-Real-IP instead.
+func GetIpAddress(r *http.Request, trustedProxyIPHeader string, trustedProxyCIDRs string) string {
+	address, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		address = r.RemoteAddr
+	}
+
+	if trustedProxyIPHeader == "" {
+		return address
+	}
+
+	trustedProxies := []*net.IPNet{}
+	for _, cidr := range strings.Fields(trustedProxyCIDRs) {
+		if _, network, err := net.ParseCIDR(cidr); err == nil {
+			trustedProxies = append(trustedProxies, network)
+		}
+	}
 
-	header := r.Header.Get(model.HEADER_FORWARDED)
-	if len(header) > 0 {
-		addresses := strings.Fields(header)
-		if len(addresses) > 0 {
-			address = strings.TrimRight(addresses[0], ",")
+	isTrustedProxy := func(ip net.IP) bool {
+		for _, network := range trustedProxies {
+			if network.Contains(ip) {
+				return true
+			}
 		}
+
+		return false
 	}
 
-	if len(address) == 0 {
-		address = r.Header.Get(model.HEADER_REAL_IP)
+	ip := net.ParseIP(address)
+	if ip == nil || !isTrustedProxy(ip) {
+		return address
 	}
 
-	if len(address) == 0 {
-		address, _, _ = net.SplitHostPort(r.RemoteAddr)
+	hops := []string{}
+	for _, value := range r.Header[http.CanonicalHeaderKey(trustedProxyIPHeader)] {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			break
+		}
+
+		address = ip.String()
+
+		if !isTrustedProxy(ip) {
+			break
+		}
+	}
+
+	// Proxies that don't set the configured header usually pass the client's address along in X-Real-IP instead
+	if strings.TrimSpace(strings.Join(hops, "")) == "" {
+		if ip := net.ParseIP(strings.TrimSpace(r.Header.Get(model.HEADER_REAL_IP))); ip != nil {
+			address = ip.String()
+		}
 	}
 
 	return address
 }
 
+var validRequestId = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// GetRequestId returns the X-Request-ID set by the client or a proxy in front of the server so that the request can be
+// followed through both of their logs. A new id is returned if there isn't one or if it can't be safely logged.
+func GetRequestId(r *http.Request) string {
+	if requestId := r.Header.Get(model.HEADER_REQUEST_ID); validRequestId.MatchString(requestId) {
+		return requestId
+	}
+
+	return model.NewId()
+}
+
 func GetHostnameFromSiteURL(siteURL string) string {
 	u, err := url.Parse(siteURL)
 	if err != nil {
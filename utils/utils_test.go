@@ -5,9 +5,12 @@ package utils
 
 import (
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/mattermost/mattermost-server/model"
 )
 
 func TestStringArrayIntersection(t *testing.T) {
@@ -49,53 +52,131 @@ func TestRemoveDuplicatesFromStringArray(t *testing.T) {
 }
 
 func TestGetIpAddress(t *testing.T) {
-	// Test with a single IP in the X-Forwarded-For
-	httpRequest1 := http.Request{
-		Header: http.Header{
-			"X-Forwarded-For": []string{"10.0.0.1"},
-			"X-Real-Ip":       []string{"10.1.0.1"},
+	const trustedProxyCIDRs = "10.0.0.0/8 fd00::/8"
+
+	for name, tc := range map[string]struct {
+		RemoteAddr           string
+		Header               http.Header
+		TrustedProxyIPHeader string
+		IgnoreProxies        bool
+		Expected             string
+	}{
+		"without any headers": {
+			RemoteAddr: "10.2.0.1:12345",
+			Expected:   "10.2.0.1",
 		},
-		RemoteAddr: "10.2.0.1:12345",
-	}
-
-	assert.Equal(t, "10.0.0.1", GetIpAddress(&httpRequest1))
-
-	// Test with multiple IPs in the X-Forwarded-For
-	httpRequest2 := http.Request{
-		Header: http.Header{
-			"X-Forwarded-For": []string{"10.0.0.1,  10.0.0.2, 10.0.0.3"},
-			"X-Real-Ip":       []string{"10.1.0.1"},
+		"from a trusted proxy": {
+			RemoteAddr: "10.2.0.1:12345",
+			Header:     http.Header{"X-Forwarded-For": []string{"203.0.113.5"}},
+			Expected:   "203.0.113.5",
 		},
-		RemoteAddr: "10.2.0.1:12345",
-	}
-
-	assert.Equal(t, "10.0.0.1", GetIpAddress(&httpRequest2))
-
-	// Test with an empty X-Forwarded-For
-	httpRequest3 := http.Request{
-		Header: http.Header{
-			"X-Forwarded-For": []string{""},
-			"X-Real-Ip":       []string{"10.1.0.1"},
+		"spoofed by an untrusted client": {
+			RemoteAddr: "198.51.100.7:12345",
+			Header:     http.Header{"X-Forwarded-For": []string{"10.0.0.1"}, "X-Real-Ip": []string{"10.1.0.1"}},
+			Expected:   "198.51.100.7",
 		},
-		RemoteAddr: "10.2.0.1:12345",
-	}
-
-	assert.Equal(t, "10.1.0.1", GetIpAddress(&httpRequest3))
-
-	// Test without an X-Fowarded-For
-	httpRequest4 := http.Request{
-		Header: http.Header{
-			"X-Real-Ip": []string{"10.1.0.1"},
+		"prepended by the client before reaching a trusted proxy": {
+			RemoteAddr: "10.2.0.1:12345",
+			Header:     http.Header{"X-Forwarded-For": []string{"192.0.2.1, 203.0.113.5"}},
+			Expected:   "203.0.113.5",
+		},
+		"through several trusted proxies": {
+			RemoteAddr: "10.2.0.1:12345",
+			Header:     http.Header{"X-Forwarded-For": []string{"192.0.2.1,  203.0.113.5, 10.0.0.3", "10.0.0.2"}},
+			Expected:   "203.0.113.5",
+		},
+		"only through trusted proxies": {
+			RemoteAddr: "10.2.0.1:12345",
+			Header:     http.Header{"X-Forwarded-For": []string{"10.0.0.3, 10.0.0.2"}},
+			Expected:   "10.0.0.3",
 		},
-		RemoteAddr: "10.2.0.1:12345",
+		"with an invalid hop": {
+			RemoteAddr: "10.2.0.1:12345",
+			Header:     http.Header{"X-Forwarded-For": []string{"203.0.113.5, unknown, 10.0.0.3"}},
+			Expected:   "10.0.0.3",
+		},
+		"with an empty header": {
+			RemoteAddr: "10.2.0.1:12345",
+			Header:     http.Header{"X-Forwarded-For": []string{""}},
+			Expected:   "10.2.0.1",
+		},
+		"from a trusted proxy that only sets X-Real-IP": {
+			RemoteAddr: "10.2.0.1:12345",
+			Header:     http.Header{"X-Real-Ip": []string{"203.0.113.5"}},
+			Expected:   "203.0.113.5",
+		},
+		"from a trusted proxy that sets both headers": {
+			RemoteAddr: "10.2.0.1:12345",
+			Header:     http.Header{"X-Forwarded-For": []string{"203.0.113.5"}, "X-Real-Ip": []string{"192.0.2.1"}},
+			Expected:   "203.0.113.5",
+		},
+		"with X-Real-IP from an untrusted client": {
+			RemoteAddr: "198.51.100.7:12345",
+			Header:     http.Header{"X-Real-Ip": []string{"203.0.113.5"}},
+			Expected:   "198.51.100.7",
+		},
+		"with X-Real-IP and proxies disabled": {
+			RemoteAddr:    "10.2.0.1:12345",
+			Header:        http.Header{"X-Real-Ip": []string{"203.0.113.5"}},
+			IgnoreProxies: true,
+			Expected:      "10.2.0.1",
+		},
+		"over IPv6": {
+			RemoteAddr: "[fd00::1]:12345",
+			Header:     http.Header{"X-Forwarded-For": []string{"2001:db8::1"}},
+			Expected:   "2001:db8::1",
+		},
+		"with a different header": {
+			RemoteAddr:           "10.2.0.1:12345",
+			Header:               http.Header{"X-Forwarded-For": []string{"192.0.2.1"}, "X-Real-Ip": []string{"203.0.113.5"}},
+			TrustedProxyIPHeader: "X-Real-IP",
+			Expected:             "203.0.113.5",
+		},
+		"with proxies disabled": {
+			RemoteAddr:    "10.2.0.1:12345",
+			Header:        http.Header{"X-Forwarded-For": []string{"203.0.113.5"}},
+			IgnoreProxies: true,
+			Expected:      "10.2.0.1",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			trustedProxyIPHeader := tc.TrustedProxyIPHeader
+			if tc.IgnoreProxies {
+				trustedProxyIPHeader = ""
+			} else if trustedProxyIPHeader == "" {
+				trustedProxyIPHeader = model.HEADER_FORWARDED
+			}
+
+			r := &http.Request{RemoteAddr: tc.RemoteAddr, Header: tc.Header}
+			assert.Equal(t, tc.Expected, GetIpAddress(r, trustedProxyIPHeader, trustedProxyCIDRs))
+		})
 	}
+}
 
-	assert.Equal(t, "10.1.0.1", GetIpAddress(&httpRequest4))
-
-	// Test without any headers
-	httpRequest5 := http.Request{
-		RemoteAddr: "10.2.0.1:12345",
+func TestGetRequestId(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Header   string
+		Expected string
+	}{
+		"set by a proxy":    {Header: "f058ebd6-02f7-4d3f-942e-904344e8cde5", Expected: "f058ebd6-02f7-4d3f-942e-904344e8cde5"},
+		"set by Mattermost": {Header: "8tmtx6kqo3y3tb4ougz8gwr5xr", Expected: "8tmtx6kqo3y3tb4ougz8gwr5xr"},
+		"missing":           {Header: ""},
+		"too long":          {Header: strings.Repeat("a", 65)},
+		"unsafe to log":     {Header: "abc\ndef"},
+		"with spaces":       {Header: "abc def"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := &http.Request{Header: http.Header{}}
+			if tc.Header != "" {
+				r.Header.Set(model.HEADER_REQUEST_ID, tc.Header)
+			}
+
+			requestId := GetRequestId(r)
+			if tc.Expected != "" {
+				assert.Equal(t, tc.Expected, requestId)
+			} else {
+				assert.True(t, model.IsValidId(requestId), "should have generated a new id")
+			}
+		})
 	}
-
-	assert.Equal(t, "10.2.0.1", GetIpAddress(&httpRequest5))
 }
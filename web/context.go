@@ -21,11 +21,13 @@ type Context struct {
 	App           *app.App
 	Session       model.Session
 	Params        *Params
+	Log           *mlog.Logger
 	Err           *model.AppError
 	T             goi18n.TranslateFunc
 	RequestId     string
 	IpAddress     string
 	Path          string
+	IsLocal       bool
 	siteURLHeader string
 }
 
@@ -48,6 +50,20 @@ func (c *Context) LogAuditWithUserId(userId, extraInfo string) {
 	}
 }
 
+// LogImpersonationAudit records a request made with an impersonation session against the admin who is
+// impersonating the session's user.
+func (c *Context) LogImpersonationAudit(r *http.Request) {
+	extraInfo := fmt.Sprintf("impersonated_user_id=%v method=%v", c.Session.UserId, r.Method)
+	if c.Err != nil {
+		extraInfo += fmt.Sprintf(" status=%v", c.Err.StatusCode)
+	}
+
+	audit := &model.Audit{UserId: c.Session.Props[model.SESSION_PROP_IMPERSONATOR_ID], IpAddress: c.IpAddress, Action: c.Path, ExtraInfo: extraInfo, SessionId: c.Session.Id}
+	if r := <-c.App.Srv.Store.Audit().Save(audit); r.Err != nil {
+		c.LogError(r.Err)
+	}
+}
+
 func (c *Context) LogError(err *model.AppError) {
 	// Filter out 404s, endless reconnects and browser compatibility errors
 	if err.StatusCode == http.StatusNotFound ||
@@ -55,8 +71,8 @@ func (c *Context) LogError(err *model.AppError) {
 		err.Id == "web.check_browser_compatibility.app_error" {
 		c.LogDebug(err)
 	} else {
-		mlog.Error(fmt.Sprintf("%v:%v code=%v rid=%v uid=%v ip=%v %v [details: %v]", c.Path, err.Where, err.StatusCode,
-			c.RequestId, c.Session.UserId, c.IpAddress, err.SystemMessage(utils.TDefault), err.DetailedError), mlog.String("user_id", c.Session.UserId))
+		c.Log.Error(fmt.Sprintf("%v:%v code=%v rid=%v uid=%v ip=%v %v [details: %v]", c.Path, err.Where, err.StatusCode,
+			c.RequestId, c.Session.UserId, c.IpAddress, err.SystemMessage(utils.TDefault), err.DetailedError))
 	}
 }
 
@@ -65,14 +81,14 @@ func (c *Context) LogInfo(err *model.AppError) {
 	if err.StatusCode == http.StatusUnauthorized {
 		c.LogDebug(err)
 	} else {
-		mlog.Info(fmt.Sprintf("%v:%v code=%v rid=%v uid=%v ip=%v %v [details: %v]", c.Path, err.Where, err.StatusCode,
-			c.RequestId, c.Session.UserId, c.IpAddress, err.SystemMessage(utils.TDefault), err.DetailedError), mlog.String("user_id", c.Session.UserId))
+		c.Log.Info(fmt.Sprintf("%v:%v code=%v rid=%v uid=%v ip=%v %v [details: %v]", c.Path, err.Where, err.StatusCode,
+			c.RequestId, c.Session.UserId, c.IpAddress, err.SystemMessage(utils.TDefault), err.DetailedError))
 	}
 }
 
 func (c *Context) LogDebug(err *model.AppError) {
-	mlog.Debug(fmt.Sprintf("%v:%v code=%v rid=%v uid=%v ip=%v %v [details: %v]", c.Path, err.Where, err.StatusCode,
-		c.RequestId, c.Session.UserId, c.IpAddress, err.SystemMessage(utils.TDefault), err.DetailedError), mlog.String("user_id", c.Session.UserId))
+	c.Log.Debug(fmt.Sprintf("%v:%v code=%v rid=%v uid=%v ip=%v %v [details: %v]", c.Path, err.Where, err.StatusCode,
+		c.RequestId, c.Session.UserId, c.IpAddress, err.SystemMessage(utils.TDefault), err.DetailedError))
 }
 
 func (c *Context) IsSystemAdmin() bool {
@@ -501,3 +517,139 @@ func (c *Context) RequireRoleName() *Context {
 
 	return c
 }
+
+func (c *Context) RequireCategoryId() *Context {
+	if c.Err != nil {
+		return c
+	}
+
+	if len(c.Params.CategoryId) != 26 {
+		c.SetInvalidUrlParam("category_id")
+	}
+	return c
+}
+
+func (c *Context) RequireThreadId() *Context {
+	if c.Err != nil {
+		return c
+	}
+
+	if len(c.Params.ThreadId) != 26 {
+		c.SetInvalidUrlParam("thread_id")
+	}
+	return c
+}
+
+// RequireAllowedFields makes sure that the fields that the client asked for can be chosen on their own.
+func (c *Context) RequireAllowedFields(allowed map[string]bool) *Context {
+	if c.Err != nil {
+		return c
+	}
+
+	for _, field := range c.Params.Fields {
+		if !allowed[field] {
+			c.SetInvalidUrlParam("fields")
+			break
+		}
+	}
+	return c
+}
+
+func (c *Context) RequireTimestamp() *Context {
+	if c.Err != nil {
+		return c
+	}
+
+	if c.Params.Timestamp <= 0 {
+		c.SetInvalidUrlParam("timestamp")
+	}
+	return c
+}
+
+func (c *Context) RequireSavedSearchId() *Context {
+	if c.Err != nil {
+		return c
+	}
+
+	if len(c.Params.SavedSearchId) != 26 {
+		c.SetInvalidUrlParam("saved_search_id")
+	}
+	return c
+}
+
+func (c *Context) RequireBlockedUserId() *Context {
+	if c.Err != nil {
+		return c
+	}
+
+	if len(c.Params.BlockedUserId) != 26 {
+		c.SetInvalidUrlParam("blocked_user_id")
+	}
+	return c
+}
+
+func (c *Context) RequireTemplateId() *Context {
+	if c.Err != nil {
+		return c
+	}
+
+	if len(c.Params.TemplateId) != 26 {
+		c.SetInvalidUrlParam("template_id")
+	}
+	return c
+}
+
+func (c *Context) RequireGroupId() *Context {
+	if c.Err != nil {
+		return c
+	}
+
+	if len(c.Params.GroupId) != 26 {
+		c.SetInvalidUrlParam("group_id")
+	}
+	return c
+}
+
+func (c *Context) RequireFieldId() *Context {
+	if c.Err != nil {
+		return c
+	}
+
+	if len(c.Params.FieldId) != 26 {
+		c.SetInvalidUrlParam("field_id")
+	}
+	return c
+}
+
+func (c *Context) RequireLinkId() *Context {
+	if c.Err != nil {
+		return c
+	}
+
+	if len(c.Params.LinkId) != 26 {
+		c.SetInvalidUrlParam("link_id")
+	}
+	return c
+}
+
+func (c *Context) RequireHistoryId() *Context {
+	if c.Err != nil {
+		return c
+	}
+
+	if len(c.Params.HistoryId) != 26 {
+		c.SetInvalidUrlParam("history_id")
+	}
+	return c
+}
+
+func (c *Context) RequireInvitationId() *Context {
+	if c.Err != nil {
+		return c
+	}
+
+	if len(c.Params.InvitationId) != 26 {
+		c.SetInvalidUrlParam("invitation_id")
+	}
+	return c
+}
@@ -6,12 +6,15 @@ package web
 import (
 	"fmt"
 	"net/http"
+	"reflect"
+	"runtime"
 	"time"
 
 	"github.com/mattermost/mattermost-server/app"
 	"github.com/mattermost/mattermost-server/mlog"
 	"github.com/mattermost/mattermost-server/model"
 	"github.com/mattermost/mattermost-server/utils"
+	"github.com/mattermost/mattermost-server/utils/tracing"
 )
 
 func (w *Web) NewHandler(h func(*Context, http.ResponseWriter, *http.Request)) http.Handler {
@@ -36,6 +39,23 @@ func (w *Web) NewStaticHandler(h func(*Context, http.ResponseWriter, *http.Reque
 	}
 }
 
+// maintenanceModeAllowedPaths can be used by anyone while maintenance mode is enabled so that system admins can still
+// log in and so that load balancers can find out about it. Websocket connections are checked once they authenticate.
+var maintenanceModeAllowedPaths = map[string]bool{
+	model.API_URL_SUFFIX + "/system/ping":    true,
+	model.API_URL_SUFFIX + "/users/login":    true,
+	model.API_URL_SUFFIX + "/users/logout":   true,
+	model.API_URL_SUFFIX + "/config/client":  true,
+	model.API_URL_SUFFIX + "/license/client": true,
+	model.API_URL_SUFFIX + "/websocket":      true,
+}
+
+// readOnlyAllowedPaths can still be written to while the database is read-only so that read-only maintenance mode can
+// be turned off again.
+var readOnlyAllowedPaths = map[string]bool{
+	model.API_URL_SUFFIX + "/system/maintenance": true,
+}
+
 type Handler struct {
 	App            *app.App
 	HandleFunc     func(*Context, http.ResponseWriter, *http.Request)
@@ -43,20 +63,63 @@ type Handler struct {
 	TrustRequester bool
 	RequireMfa     bool
 	IsStatic       bool
+
+	// RequirePermission, if set, is a system wide permission that the session must have before the request is
+	// handled, such as for System Console routes that aren't limited to system admins.
+	RequirePermission *model.Permission
+
+	// IsLocal handlers serve requests made over the local mode socket. Only users with access to the server itself
+	// can connect to it, so the requests are made as a system admin without a session.
+	IsLocal bool
 }
 
 func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	now := time.Now()
-	mlog.Debug(fmt.Sprintf("%v - %v", r.Method, r.URL.Path))
 
 	c := &Context{}
 	c.App = h.App
 	c.T, _ = utils.GetTranslationsAndLocale(w, r)
-	c.RequestId = model.NewId()
-	c.IpAddress = utils.GetIpAddress(r)
+	c.RequestId = utils.GetRequestId(r)
+	c.IpAddress = c.App.GetIpAddress(r)
 	c.Params = ParamsFromRequest(r)
+	c.Log = c.App.Log.With(
+		mlog.String("path", r.URL.Path),
+		mlog.String("request_id", c.RequestId),
+		mlog.String("ip_addr", c.IpAddress),
+		mlog.String("method", r.Method),
+	)
+
+	c.Log.Debug(fmt.Sprintf("%v - %v", r.Method, r.URL.Path))
+
+	var trace *tracing.Trace
+	if !h.IsStatic && r.URL.Path != model.API_URL_SUFFIX+"/websocket" && c.App.ShouldTraceRequest() {
+		trace = tracing.Start(&model.RequestTrace{
+			RequestId: c.RequestId,
+			Handler:   tracing.FuncName(runtime.FuncForPC(reflect.ValueOf(h.HandleFunc).Pointer())),
+			Method:    r.Method,
+			Path:      r.URL.Path,
+		})
+
+		// The trace is finished however the request ends so that its goroutine stops being traced
+		defer func() {
+			record := trace.Finish()
+			record.UserId = c.Session.UserId
+			if c.Err != nil {
+				record.ErrorId = c.Err.Id
+			}
+			c.App.RecordRequestTrace(record)
+		}()
+	}
+
+	// Anything that the request is passed on to can log the same id
+	r.Header.Set(model.HEADER_REQUEST_ID, c.RequestId)
 
 	token, tokenLocation := app.ParseAuthTokenFromRequest(r)
+	if h.IsLocal {
+		token, tokenLocation = "", app.TokenLocationNotFound
+		c.Session = app.LocalModeSession()
+		c.IsLocal = true
+	}
 
 	// CSRF Check
 	if tokenLocation == app.TokenLocationCookie && h.RequireSession && !h.TrustRequester {
@@ -82,51 +145,99 @@ func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "GET" {
 			w.Header().Set("Expires", "0")
 		}
+
+		// Fail fast instead of waiting for queries to time out while the database is down, unless reads can still be
+		// served from a replica
+		if !c.App.Srv.Store.IsMasterHealthy() && !c.App.Srv.Store.IsReadOnly() {
+			c.Err = model.NewAppError("ServeHTTP", "api.context.database_unavailable.app_error", nil, "", http.StatusServiceUnavailable)
+			token = ""
+		}
 	}
 
 	if len(token) != 0 {
 		session, err := c.App.GetSession(token)
 
 		if err != nil {
-			mlog.Info(fmt.Sprintf("Invalid session err=%v", err.Error()))
+			c.Log.Info(fmt.Sprintf("Invalid session err=%v", err.Error()))
 			if err.StatusCode == http.StatusInternalServerError {
 				c.Err = err
 			} else if h.RequireSession {
 				c.RemoveSessionCookie(w, r)
-				c.Err = model.NewAppError("ServeHTTP", "api.context.session_expired.app_error", nil, "token="+token, http.StatusUnauthorized)
+				if err.Id == app.SESSION_IDLE_TIMEOUT_ERROR_ID {
+					// Clients tell users that they were signed out for being inactive instead of their session expiring
+					c.Err = err
+				} else {
+					c.Err = model.NewAppError("ServeHTTP", "api.context.session_expired.app_error", nil, "token="+token, http.StatusUnauthorized)
+				}
 			}
 		} else if !session.IsOAuth && tokenLocation == app.TokenLocationQueryString {
 			c.Err = model.NewAppError("ServeHTTP", "api.context.token_provided.app_error", nil, "token="+token, http.StatusUnauthorized)
+		} else if err := c.App.CheckSessionFingerprint(session, r); err != nil {
+			if err.StatusCode == http.StatusUnauthorized {
+				c.RemoveSessionCookie(w, r)
+			}
+			c.Err = err
 		} else {
 			c.Session = *session
+			c.Log = c.Log.With(mlog.String("user_id", c.Session.UserId))
+
+			if session.TermsOfServiceRequired {
+				w.Header().Set(model.HEADER_TERMS_OF_SERVICE_REQUIRED, "true")
+			}
 		}
 
 		// Rate limit by UserID
-		if c.App.Srv.RateLimiter != nil && c.App.Srv.RateLimiter.UserIdRateLimit(c.Session.UserId, w) {
+		if rateLimiter := c.App.Srv.RateLimiter(); rateLimiter != nil && rateLimiter.UserIdRateLimit(c.Session.UserId, w) {
 			return
 		}
 	}
 
 	c.Path = r.URL.Path
 
-	if c.Err == nil && h.RequireSession {
+	// Disabled endpoints are refused regardless of the permissions of whoever requested them
+	if c.Err == nil && !h.IsStatic && c.App.Config().ServiceSettings.IsAPIEndpointDisabled(r.Method, r.URL.Path) {
+		c.Err = model.NewAppError("ServeHTTP", "api.context.api_endpoint_disabled.app_error", nil, r.Method+" "+r.URL.Path, http.StatusForbidden)
+	}
+
+	if c.Err == nil && !h.IsStatic && !maintenanceModeAllowedPaths[r.URL.Path] {
+		c.Err = c.App.CheckMaintenanceMode(c.Session)
+	}
+
+	// Writes are refused before doing any work for them while the database is read-only so that clients can quickly
+	// queue them to try again later
+	if c.Err == nil && !h.IsStatic && r.Method != "GET" && r.Method != "HEAD" && !readOnlyAllowedPaths[r.URL.Path] {
+		c.Err = c.App.CheckReadOnly()
+	}
+
+	if c.Err == nil && h.RequireSession && !h.IsLocal {
 		c.SessionRequired()
 	}
 
-	if c.Err == nil && h.RequireMfa {
+	if c.Err == nil && h.RequireMfa && !h.IsLocal {
 		c.MfaRequired()
 	}
 
+	if c.Err == nil && h.RequirePermission != nil && !h.IsLocal && !c.App.SessionHasPermissionTo(c.Session, h.RequirePermission) {
+		c.SetPermissionError(h.RequirePermission)
+	}
+
 	if c.Err == nil {
 		h.HandleFunc(c, w, r)
 	}
 
+	if c.Session.IsImpersonation {
+		c.LogImpersonationAudit(r)
+	}
+
 	// Handle errors that have occurred
 	if c.Err != nil {
 		c.Err.Translate(c.T)
 		c.Err.RequestId = c.RequestId
+		if c.Err.Code == "" {
+			c.Err.Code = model.GetAppErrorCode(c.Err.Id, c.Err.StatusCode)
+		}
 
-		if c.Err.Id == "api.context.session_expired.app_error" {
+		if c.Err.Id == "api.context.session_expired.app_error" || c.Err.Id == app.SESSION_IDLE_TIMEOUT_ERROR_ID {
 			c.LogInfo(c.Err)
 		} else {
 			c.LogError(c.Err)
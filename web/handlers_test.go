@@ -0,0 +1,103 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package web
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-server/mlog"
+	"github.com/mattermost/mattermost-server/model"
+)
+
+func TestHandlerRequestId(t *testing.T) {
+	th := Setup().InitBasic()
+	defer th.TearDown()
+
+	dir, err := ioutil.TempDir("", "")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	logFile := filepath.Join(dir, "mattermost.log")
+
+	logger := th.App.Log
+	defer func() { th.App.Log = logger }()
+	th.App.Log = mlog.NewLogger(&mlog.LoggerConfiguration{
+		EnableFile:   true,
+		FileJson:     true,
+		FileLevel:    mlog.LevelDebug,
+		FileLocation: logFile,
+	})
+
+	// findLogs returns every log record that was written for the request
+	findLogs := func(t *testing.T, requestId string) []map[string]interface{} {
+		data, err := ioutil.ReadFile(logFile)
+		require.Nil(t, err)
+
+		records := []map[string]interface{}{}
+		for _, line := range strings.Split(string(data), "\n") {
+			record := map[string]interface{}{}
+			if json.Unmarshal([]byte(line), &record) == nil && record["request_id"] == requestId {
+				records = append(records, record)
+			}
+		}
+
+		return records
+	}
+
+	// postToMissingHook makes a request that fails so that its error is logged
+	postToMissingHook := func(t *testing.T, requestId string) (*http.Response, *model.AppError) {
+		r, err := http.NewRequest("POST", ApiClient.Url+"/hooks/"+model.NewId(), strings.NewReader(`{"text": "test"}`))
+		require.Nil(t, err)
+		if requestId != "" {
+			r.Header.Set(model.HEADER_REQUEST_ID, requestId)
+		}
+
+		resp, err := http.DefaultClient.Do(r)
+		require.Nil(t, err)
+		defer resp.Body.Close()
+
+		appErr := model.AppErrorFromJson(resp.Body)
+		require.NotNil(t, appErr)
+
+		return resp, appErr
+	}
+
+	t.Run("generated", func(t *testing.T) {
+		resp, appErr := postToMissingHook(t, "")
+
+		requestId := resp.Header.Get(model.HEADER_REQUEST_ID)
+		assert.True(t, model.IsValidId(requestId))
+		assert.Equal(t, requestId, appErr.RequestId)
+
+		records := findLogs(t, requestId)
+		require.NotEmpty(t, records, "should have logged the error with the request id")
+		assert.Contains(t, records[len(records)-1]["msg"], appErr.Where)
+	})
+
+	t.Run("set by the client", func(t *testing.T) {
+		resp, appErr := postToMissingHook(t, "from-the-load-balancer")
+
+		assert.Equal(t, "from-the-load-balancer", resp.Header.Get(model.HEADER_REQUEST_ID))
+		assert.Equal(t, "from-the-load-balancer", appErr.RequestId)
+		assert.NotEmpty(t, findLogs(t, "from-the-load-balancer"))
+	})
+
+	t.Run("unsafe to log", func(t *testing.T) {
+		resp, appErr := postToMissingHook(t, "not safe to log")
+
+		requestId := resp.Header.Get(model.HEADER_REQUEST_ID)
+		assert.True(t, model.IsValidId(requestId), "should have replaced the request id")
+		assert.Equal(t, requestId, appErr.RequestId)
+		assert.Empty(t, findLogs(t, "not safe to log"))
+	})
+}
@@ -47,10 +47,28 @@ type Params struct {
 	ActionId       string
 	RoleId         string
 	RoleName       string
+	CategoryId     string
+	ThreadId       string
+	GroupId        string
+	FieldId        string
+	LinkId         string
+	InvitationId   string
+	PermalinkCode  string
+	HistoryId      string
+	SavedSearchId  string
+	BlockedUserId  string
+	TemplateId     string
+	Timestamp      int64
 	Page           int
 	PerPage        int
 	LogsPerPage    int
 	Permanent      bool
+
+	// Fields are the only fields that the client wants in each object of the response, if it supports choosing them.
+	Fields []string
+
+	// IncludeMetadata is set by clients that want the file infos, custom emojis and link embeds of posts.
+	IncludeMetadata bool
 }
 
 func ParamsFromRequest(r *http.Request) *Params {
@@ -167,6 +185,56 @@ func ParamsFromRequest(r *http.Request) *Params {
 		params.RoleName = val
 	}
 
+	if val, ok := props["category_id"]; ok {
+		params.CategoryId = val
+	}
+
+	if val, ok := props["thread_id"]; ok {
+		params.ThreadId = val
+	}
+
+	if val, ok := props["group_id"]; ok {
+		params.GroupId = val
+	}
+
+	if val, ok := props["field_id"]; ok {
+		params.FieldId = val
+	}
+
+	if val, ok := props["link_id"]; ok {
+		params.LinkId = val
+	}
+
+	if val, ok := props["invitation_id"]; ok {
+		params.InvitationId = val
+	}
+
+	if val, ok := props["permalink_code"]; ok {
+		params.PermalinkCode = val
+	}
+
+	if val, ok := props["history_id"]; ok {
+		params.HistoryId = val
+	}
+
+	if val, ok := props["saved_search_id"]; ok {
+		params.SavedSearchId = val
+	}
+
+	if val, ok := props["blocked_user_id"]; ok {
+		params.BlockedUserId = val
+	}
+
+	if val, ok := props["template_id"]; ok {
+		params.TemplateId = val
+	}
+
+	if val, ok := props["timestamp"]; ok {
+		if timestamp, err := strconv.ParseInt(val, 10, 64); err == nil {
+			params.Timestamp = timestamp
+		}
+	}
+
 	if val, err := strconv.Atoi(query.Get("page")); err != nil || val < 0 {
 		params.Page = PAGE_DEFAULT
 	} else {
@@ -177,6 +245,18 @@ func ParamsFromRequest(r *http.Request) *Params {
 		params.Permanent = val
 	}
 
+	if val, err := strconv.ParseBool(query.Get("include_metadata")); err == nil {
+		params.IncludeMetadata = val
+	}
+
+	if val := query.Get("fields"); val != "" {
+		for _, field := range strings.Split(val, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				params.Fields = append(params.Fields, field)
+			}
+		}
+	}
+
 	if val, err := strconv.Atoi(query.Get("per_page")); err != nil || val < 0 {
 		params.PerPage = PER_PAGE_DEFAULT
 	} else if val > PER_PAGE_MAXIMUM {
@@ -0,0 +1,186 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package web
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/mlog"
+)
+
+// PRECOMPRESSED_MIN_SIZE is the size below which files aren't worth compressing ahead of time.
+const PRECOMPRESSED_MIN_SIZE = 1024
+
+type staticEncoding struct {
+	name      string
+	extension string
+}
+
+// staticEncodings are the encodings of precompressed files in order of preference. Brotli files are only served
+// if they were built along with the client since there isn't an encoder available to generate them.
+var staticEncodings = []staticEncoding{
+	{name: "br", extension: ".br"},
+	{name: "gzip", extension: ".gz"},
+}
+
+var precompressedExtensions = map[string]bool{
+	".js":   true,
+	".css":  true,
+	".svg":  true,
+	".json": true,
+}
+
+// precompressedHandler serves a compressed copy of a file from the directory in place of the file itself when
+// there's one next to it and the client accepts its encoding. Everything else is left to the given handler.
+func precompressedHandler(dir string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := path.Clean("/" + r.URL.Path)
+		if !precompressedExtensions[path.Ext(name)] {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		addVary(w.Header(), "Accept-Encoding")
+
+		accepted := acceptedEncodings(r)
+		for _, encoding := range staticEncodings {
+			if !accepted[encoding.name] {
+				continue
+			}
+
+			file, err := os.Open(filepath.Join(dir, filepath.FromSlash(name)+encoding.extension))
+			if err != nil {
+				continue
+			}
+			defer file.Close()
+
+			stat, err := file.Stat()
+			if err != nil || stat.IsDir() {
+				continue
+			}
+
+			w.Header().Set("Content-Encoding", encoding.name)
+			// ServeContent picks the content type from the name of the uncompressed file
+			http.ServeContent(w, r, name, stat.ModTime(), file)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// acceptedEncodings returns the content encodings allowed by the request's Accept-Encoding header.
+func acceptedEncodings(r *http.Request) map[string]bool {
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		params := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		if name == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		qualities[name] = quality
+	}
+
+	accepted := make(map[string]bool)
+	for _, encoding := range staticEncodings {
+		if q, ok := qualities[encoding.name]; ok {
+			accepted[encoding.name] = q > 0
+		} else if q, ok := qualities["*"]; ok {
+			accepted[encoding.name] = q > 0
+		}
+	}
+
+	return accepted
+}
+
+func addVary(header http.Header, value string) {
+	for _, existing := range header["Vary"] {
+		for _, v := range strings.Split(existing, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), value) {
+				return
+			}
+		}
+	}
+
+	header.Add("Vary", value)
+}
+
+// generatePrecompressedFiles writes a gzipped copy next to each file in the directory that can be served
+// precompressed and doesn't already have an up to date one.
+func generatePrecompressedFiles(dir string) error {
+	return filepath.Walk(dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() || !precompressedExtensions[filepath.Ext(filePath)] || info.Size() < PRECOMPRESSED_MIN_SIZE {
+			return nil
+		}
+
+		if compressed, err := os.Stat(filePath + ".gz"); err == nil && !compressed.ModTime().Before(info.ModTime()) {
+			return nil
+		}
+
+		if err := gzipFile(filePath); err != nil {
+			mlog.Warn("Unable to precompress static file", mlog.String("path", filePath), mlog.Err(err))
+		}
+
+		return nil
+	})
+}
+
+func gzipFile(filePath string) error {
+	src, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	// the compressed file is written somewhere else first so that a partial one is never served
+	tmp, err := ioutil.TempFile(filepath.Dir(filePath), "."+filepath.Base(filePath)+".gz")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	gz, err := gzip.NewWriterLevel(tmp, gzip.BestCompression)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if _, err := io.Copy(gz, src); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := gz.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filePath+".gz")
+}
@@ -9,7 +9,6 @@ import (
 	"strings"
 
 	"github.com/mattermost/mattermost-server/app"
-	"github.com/mattermost/mattermost-server/mlog"
 	"github.com/mattermost/mattermost-server/model"
 )
 
@@ -89,6 +88,7 @@ func completeSaml(c *Context, w http.ResponseWriter, r *http.Request) {
 	if user, err := samlInterface.DoLogin(encodedXML, relayProps); err != nil {
 		if action == model.OAUTH_ACTION_MOBILE {
 			err.Translate(c.T)
+			err.RequestId = c.RequestId
 			w.Write([]byte(err.ToJson()))
 		} else {
 			c.Err = err
@@ -108,7 +108,7 @@ func completeSaml(c *Context, w http.ResponseWriter, r *http.Request) {
 			if len(teamId) > 0 {
 				c.App.Go(func() {
 					if err := c.App.AddUserToTeamByTeamId(teamId, user); err != nil {
-						mlog.Error(err.Error())
+						c.Log.Error(err.Error())
 					} else {
 						c.App.AddDirectChannels(teamId, user)
 					}
@@ -122,7 +122,7 @@ func completeSaml(c *Context, w http.ResponseWriter, r *http.Request) {
 			c.LogAuditWithUserId(user.Id, "Revoked all sessions for user")
 			c.App.Go(func() {
 				if err := c.App.SendSignInChangeEmail(user.Email, strings.Title(model.USER_AUTH_SERVICE_SAML)+" SSO", user.Locale, c.App.GetSiteURL()); err != nil {
-					mlog.Error(err.Error())
+					c.Log.Error(err.Error())
 				}
 			})
 		}
@@ -6,7 +6,9 @@ package web
 import (
 	"fmt"
 	"net/http"
+	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/NYTimes/gziphandler"
@@ -21,7 +23,15 @@ func (w *Web) InitStatic() {
 		staticDir, _ := utils.FindDir(model.CLIENT_DIR)
 		mlog.Debug(fmt.Sprintf("Using client directory at %v", staticDir))
 
-		staticHandler := staticHandler(http.StripPrefix("/static/", http.FileServer(http.Dir(staticDir))))
+		if *w.App.Config().ServiceSettings.PrecompressStaticFiles {
+			go func() {
+				if err := generatePrecompressedFiles(staticDir); err != nil {
+					mlog.Warn("Unable to precompress static files", mlog.String("dir", staticDir), mlog.Err(err))
+				}
+			}()
+		}
+
+		staticHandler := newStaticFilesHandler(staticDir)
 		pluginHandler := pluginHandler(w.App.Config, http.StripPrefix("/static/plugins/", http.FileServer(http.Dir(*w.App.Config().PluginSettings.ClientDirectory))))
 
 		if *w.App.Config().ServiceSettings.WebserverMode == "gzip" {
@@ -57,9 +67,24 @@ func root(c *Context, w http.ResponseWriter, r *http.Request) {
 	http.ServeFile(w, r, filepath.Join(staticDir, "root.html"))
 }
 
+// newStaticFilesHandler serves the client's files under /static/, preferring precompressed copies of them.
+func newStaticFilesHandler(staticDir string) http.Handler {
+	return staticHandler(http.StripPrefix("/static/", precompressedHandler(staticDir, http.FileServer(http.Dir(staticDir)))))
+}
+
+// hashedFilenameRegexp matches the names of files built by the client with a hash of their contents, which can be
+// cached forever since a change to them produces a different name.
+var hashedFilenameRegexp = regexp.MustCompile(`[.-][0-9a-f]{8,}\.`)
+
 func staticHandler(handler http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Cache-Control", "max-age=31556926, public")
+		if name := path.Base(r.URL.Path); name == "root.html" {
+			w.Header().Set("Cache-Control", "no-cache, max-age=31556926, public")
+		} else if hashedFilenameRegexp.MatchString(name) {
+			w.Header().Set("Cache-Control", "max-age=31556926, public, immutable")
+		} else {
+			w.Header().Set("Cache-Control", "max-age=31556926, public")
+		}
 		if strings.HasSuffix(r.URL.Path, "/") {
 			http.NotFound(w, r)
 			return
@@ -0,0 +1,169 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package web
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupStaticDirForTest(t *testing.T) (string, []byte) {
+	dir, err := ioutil.TempDir("", "static")
+	require.Nil(t, err)
+
+	script := []byte(strings.Repeat("console.log('hello world');\n", 200))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "main.0123abcd4567.js"), script, 0644))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "manifest.json"), []byte(`{"name": "Mattermost"}`), 0644))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "root.html"), []byte("<html></html>"), 0644))
+
+	return dir, script
+}
+
+func getStaticFileForTest(handler http.Handler, path, acceptEncoding string) *http.Response {
+	r := httptest.NewRequest("GET", "http://localhost:8065"+path, nil)
+	if acceptEncoding != "" {
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w.Result()
+}
+
+func TestPrecompressedStaticFiles(t *testing.T) {
+	dir, script := setupStaticDirForTest(t)
+	defer os.RemoveAll(dir)
+
+	require.Nil(t, generatePrecompressedFiles(dir))
+
+	_, err := os.Stat(filepath.Join(dir, "main.0123abcd4567.js.gz"))
+	require.Nil(t, err, "should have compressed the script")
+	_, err = os.Stat(filepath.Join(dir, "manifest.json.gz"))
+	assert.True(t, os.IsNotExist(err), "shouldn't have compressed a small file")
+	_, err = os.Stat(filepath.Join(dir, "root.html.gz"))
+	assert.True(t, os.IsNotExist(err), "shouldn't have compressed root.html")
+
+	handler := newStaticFilesHandler(dir)
+
+	t.Run("without an encoding", func(t *testing.T) {
+		resp := getStaticFileForTest(handler, "/static/main.0123abcd4567.js", "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		body, err := ioutil.ReadAll(resp.Body)
+		require.Nil(t, err)
+
+		assert.Equal(t, script, body)
+		assert.Equal(t, "", resp.Header.Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", resp.Header.Get("Vary"))
+		assert.Equal(t, "max-age=31556926, public, immutable", resp.Header.Get("Cache-Control"))
+	})
+
+	t.Run("with gzip", func(t *testing.T) {
+		resp := getStaticFileForTest(handler, "/static/main.0123abcd4567.js", "gzip, deflate")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", resp.Header.Get("Vary"))
+		assert.Equal(t, "max-age=31556926, public, immutable", resp.Header.Get("Cache-Control"))
+		assert.Equal(t, mime.TypeByExtension(".js"), resp.Header.Get("Content-Type"))
+
+		gz, err := gzip.NewReader(resp.Body)
+		require.Nil(t, err)
+		body, err := ioutil.ReadAll(gz)
+		require.Nil(t, err)
+
+		assert.Equal(t, script, body)
+	})
+
+	t.Run("with gzip refused", func(t *testing.T) {
+		resp := getStaticFileForTest(handler, "/static/main.0123abcd4567.js", "gzip;q=0, deflate")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		body, err := ioutil.ReadAll(resp.Body)
+		require.Nil(t, err)
+
+		assert.Equal(t, "", resp.Header.Get("Content-Encoding"))
+		assert.Equal(t, script, body)
+	})
+
+	t.Run("brotli is preferred when it's been built", func(t *testing.T) {
+		brotli := []byte("not really brotli")
+		require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "main.0123abcd4567.js.br"), brotli, 0644))
+		defer os.Remove(filepath.Join(dir, "main.0123abcd4567.js.br"))
+
+		resp := getStaticFileForTest(handler, "/static/main.0123abcd4567.js", "gzip, br")
+		body, err := ioutil.ReadAll(resp.Body)
+		require.Nil(t, err)
+
+		assert.Equal(t, "br", resp.Header.Get("Content-Encoding"))
+		assert.Equal(t, brotli, body)
+
+		resp = getStaticFileForTest(handler, "/static/main.0123abcd4567.js", "gzip")
+		assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+	})
+
+	t.Run("files without a hash aren't immutable", func(t *testing.T) {
+		resp := getStaticFileForTest(handler, "/static/manifest.json", "gzip")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.Equal(t, "", resp.Header.Get("Content-Encoding"))
+		assert.Equal(t, "max-age=31556926, public", resp.Header.Get("Cache-Control"))
+	})
+
+	t.Run("root.html isn't cached", func(t *testing.T) {
+		resp := getStaticFileForTest(handler, "/static/root.html", "gzip")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		body, err := ioutil.ReadAll(resp.Body)
+		require.Nil(t, err)
+
+		assert.Equal(t, "", resp.Header.Get("Content-Encoding"))
+		assert.Equal(t, "no-cache, max-age=31556926, public", resp.Header.Get("Cache-Control"))
+		assert.Equal(t, []byte("<html></html>"), body)
+	})
+}
+
+func TestGeneratePrecompressedFilesSkipsUpToDateFiles(t *testing.T) {
+	dir, _ := setupStaticDirForTest(t)
+	defer os.RemoveAll(dir)
+
+	require.Nil(t, generatePrecompressedFiles(dir))
+
+	compressedPath := filepath.Join(dir, "main.0123abcd4567.js.gz")
+	marker := []byte("already compressed")
+	require.Nil(t, ioutil.WriteFile(compressedPath, marker, 0644))
+
+	require.Nil(t, generatePrecompressedFiles(dir))
+
+	compressed, err := ioutil.ReadFile(compressedPath)
+	require.Nil(t, err)
+	assert.True(t, bytes.Equal(marker, compressed), "shouldn't have replaced an up to date file")
+}
+
+func TestAcceptedEncodings(t *testing.T) {
+	for header, expected := range map[string]map[string]bool{
+		"":                   {},
+		"gzip":               {"gzip": true},
+		"gzip, br":           {"gzip": true, "br": true},
+		"br;q=0.5, gzip;q=0": {"gzip": false, "br": true},
+		"*":                  {"gzip": true, "br": true},
+		"*;q=0, gzip":        {"gzip": true, "br": false},
+	} {
+		r := httptest.NewRequest("GET", "http://localhost:8065/static/main.js", nil)
+		r.Header.Set("Accept-Encoding", header)
+
+		assert.Equal(t, expected, acceptedEncodings(r), header)
+	}
+}
@@ -57,10 +57,12 @@ func CheckClientCompatability(agentString string) bool {
 
 func Handle404(a *app.App, w http.ResponseWriter, r *http.Request) {
 	err := model.NewAppError("Handle404", "api.context.404.app_error", nil, "", http.StatusNotFound)
+	err.RequestId = utils.GetRequestId(r)
 
-	mlog.Debug(fmt.Sprintf("%v: code=404 ip=%v", r.URL.Path, utils.GetIpAddress(r)))
+	mlog.Debug(fmt.Sprintf("%v: code=404 ip=%v", r.URL.Path, a.GetIpAddress(r)), mlog.String("request_id", err.RequestId))
 
 	if IsApiCall(r) {
+		w.Header().Set(model.HEADER_REQUEST_ID, err.RequestId)
 		w.WriteHeader(err.StatusCode)
 		err.DetailedError = "There doesn't appear to be an api call for the url='" + r.URL.Path + "'.  Typo? are you missing a team_id or user_id as part of the url?"
 		w.Write([]byte(err.ToJson()))
@@ -136,7 +136,7 @@ func TestMain(m *testing.M) {
 	}
 
 	testStoreContainer = container
-	testStore = &persistentTestStore{store.NewLayeredStore(sqlstore.NewSqlSupplier(*settings, nil), nil, nil)}
+	testStore = &persistentTestStore{store.NewLayeredStore(sqlstore.NewSqlSupplier(*settings, nil, nil), nil, nil)}
 
 	defer func() {
 		StopTestStore()
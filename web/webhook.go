@@ -12,7 +12,6 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/gorilla/schema"
 
-	"github.com/mattermost/mattermost-server/mlog"
 	"github.com/mattermost/mattermost-server/model"
 )
 
@@ -57,15 +56,17 @@ func incomingWebhook(c *Context, w http.ResponseWriter, r *http.Request) {
 	}
 
 	if c.App.Config().LogSettings.EnableWebhookDebugging {
-		mlog.Debug(fmt.Sprint("Incoming webhook received. Content=", incomingWebhookPayload.ToJson()))
+		c.Log.Debug(fmt.Sprint("Incoming webhook received. Content=", incomingWebhookPayload.ToJson()))
 	}
 
-	err = c.App.HandleIncomingWebhook(id, incomingWebhookPayload)
+	post, err := c.App.HandleIncomingWebhook(id, incomingWebhookPayload)
 	if err != nil {
 		c.Err = err
 		return
 	}
 
+	// The body stays "ok" for Slack compatibility, so the post is only passed back for updating or deleting it later
+	w.Header().Set(model.HEADER_POST_ID, post.Id)
 	w.Header().Set("Content-Type", "text/plain")
 	w.Write([]byte("ok"))
 }
@@ -97,6 +97,38 @@ func TestIncomingWebhook(t *testing.T) {
 		assert.True(t, resp.StatusCode == http.StatusOK)
 	})
 
+	t.Run("WebhookUpdateAndDeletePost", func(t *testing.T) {
+		resp, err := http.Post(url, "application/json", strings.NewReader("{\"text\": \"Build is running\"}"))
+		require.Nil(t, err)
+		require.True(t, resp.StatusCode == http.StatusOK)
+
+		postId := resp.Header.Get(model.HEADER_POST_ID)
+		require.NotEmpty(t, postId)
+
+		resp, err = http.Post(url, "application/json", strings.NewReader(fmt.Sprintf("{\"text\": \"Build passed\", \"update_post_id\": \"%s\"}", postId)))
+		require.Nil(t, err)
+		assert.True(t, resp.StatusCode == http.StatusOK)
+		assert.Equal(t, postId, resp.Header.Get(model.HEADER_POST_ID))
+
+		post, appErr := th.App.GetSinglePost(postId)
+		require.Nil(t, appErr)
+		assert.Equal(t, "Build passed", post.Message)
+
+		userPost, appErr := th.App.CreatePost(&model.Post{UserId: th.BasicUser.Id, ChannelId: th.BasicChannel.Id, Message: "Build is running"}, th.BasicChannel, false)
+		require.Nil(t, appErr)
+
+		resp, err = http.Post(url, "application/json", strings.NewReader(fmt.Sprintf("{\"text\": \"Build passed\", \"update_post_id\": \"%s\"}", userPost.Id)))
+		require.Nil(t, err)
+		assert.True(t, resp.StatusCode == http.StatusForbidden)
+
+		resp, err = http.Post(url, "application/json", strings.NewReader(fmt.Sprintf("{\"delete_post_id\": \"%s\"}", postId)))
+		require.Nil(t, err)
+		assert.True(t, resp.StatusCode == http.StatusOK)
+
+		_, appErr = th.App.GetSinglePost(postId)
+		assert.NotNil(t, appErr)
+	})
+
 	t.Run("WebhookExperimentReadOnly", func(t *testing.T) {
 		th.App.UpdateConfig(func(cfg *model.Config) { *cfg.TeamSettings.ExperimentalTownSquareIsReadOnly = false })
 		_, err := http.Post(url, "application/json", strings.NewReader(fmt.Sprintf("{\"text\":\"this is a test\", \"channel\":\"%s\"}", model.DEFAULT_CHANNEL)))
@@ -205,6 +237,50 @@ func TestIncomingWebhook(t *testing.T) {
 		assert.True(t, resp.StatusCode == http.StatusForbidden)
 	})
 
+	t.Run("AllowedChannelsWebhook", func(t *testing.T) {
+		allowedChannel, err := th.App.CreateChannel(&model.Channel{TeamId: th.BasicTeam.Id, Name: model.NewId(), DisplayName: model.NewId(), Type: model.CHANNEL_OPEN, CreatorId: th.BasicUser.Id}, true)
+		require.Nil(t, err)
+
+		otherChannel, err := th.App.CreateChannel(&model.Channel{TeamId: th.BasicTeam.Id, Name: model.NewId(), DisplayName: model.NewId(), Type: model.CHANNEL_OPEN, CreatorId: th.BasicUser.Id}, true)
+		require.Nil(t, err)
+
+		hook, err := th.App.CreateIncomingWebhookForChannel(th.BasicUser.Id, th.BasicChannel, &model.IncomingWebhook{ChannelId: th.BasicChannel.Id, AllowedChannelIds: model.StringArray{allowedChannel.Id}})
+		require.Nil(t, err)
+
+		hooks, err := th.App.GetIncomingWebhooksForTeamPage(th.BasicTeam.Id, 0, 1000)
+		require.Nil(t, err)
+		found := false
+		for _, h := range hooks {
+			if h.Id == hook.Id {
+				found = true
+				assert.Equal(t, model.StringArray{allowedChannel.Id}, h.AllowedChannelIds)
+			}
+		}
+		assert.True(t, found)
+
+		url := ApiClient.Url + "/hooks/" + hook.Id
+
+		resp, err2 := http.Post(url, "application/json", strings.NewReader("{\"text\":\"this is a test\"}"))
+		require.Nil(t, err2)
+		assert.True(t, resp.StatusCode == http.StatusOK)
+
+		resp, err2 = http.Post(url, "application/json", strings.NewReader(fmt.Sprintf("{\"text\":\"this is a test\", \"channel\":\"%s\"}", allowedChannel.Name)))
+		require.Nil(t, err2)
+		assert.True(t, resp.StatusCode == http.StatusOK)
+
+		resp, err2 = http.Post(url, "application/json", strings.NewReader(fmt.Sprintf("{\"text\":\"this is a test\", \"channel\":\"%s\"}", otherChannel.Name)))
+		require.Nil(t, err2)
+		assert.True(t, resp.StatusCode == http.StatusForbidden)
+
+		hook.ChannelLocked = true
+		hook, err = th.App.UpdateIncomingWebhook(hook, hook)
+		require.Nil(t, err)
+
+		resp, err2 = http.Post(url, "application/json", strings.NewReader(fmt.Sprintf("{\"text\":\"this is a test\", \"channel\":\"%s\"}", allowedChannel.Name)))
+		require.Nil(t, err2)
+		assert.True(t, resp.StatusCode == http.StatusForbidden)
+	})
+
 	t.Run("DisableWebhooks", func(t *testing.T) {
 		th.App.UpdateConfig(func(cfg *model.Config) { cfg.ServiceSettings.EnableIncomingWebhooks = false })
 		resp, err := http.Post(url, "application/json", strings.NewReader("{\"text\":\"this is a test\"}"))
@@ -4,6 +4,7 @@
 package wsapi
 
 import (
+	"github.com/mattermost/mattermost-server/app"
 	"github.com/mattermost/mattermost-server/mlog"
 	"github.com/mattermost/mattermost-server/model"
 )
@@ -11,6 +12,8 @@ import (
 func (api *API) InitStatus() {
 	api.Router.Handle("get_statuses", api.ApiWebSocketHandler(api.getStatuses))
 	api.Router.Handle("get_statuses_by_ids", api.ApiWebSocketHandler(api.getStatusesByIds))
+	api.Router.Handle("subscribe_statuses", api.ApiWebSocketConnHandler(api.subscribeStatuses))
+	api.Router.Handle("unsubscribe_statuses", api.ApiWebSocketConnHandler(api.unsubscribeStatuses))
 }
 
 func (api *API) getStatuses(req *model.WebSocketRequest) (map[string]interface{}, *model.AppError) {
@@ -32,3 +35,24 @@ func (api *API) getStatusesByIds(req *model.WebSocketRequest) (map[string]interf
 
 	return statusMap, nil
 }
+
+func (api *API) subscribeStatuses(conn *app.WebConn, req *model.WebSocketRequest) (map[string]interface{}, *model.AppError) {
+	userIds := model.ArrayFromInterface(req.Data["user_ids"])
+	if len(userIds) == 0 {
+		return nil, NewInvalidWebSocketParamError(req.Action, "user_ids")
+	}
+
+	for _, userId := range userIds {
+		if !model.IsValidId(userId) {
+			return nil, NewInvalidWebSocketParamError(req.Action, "user_ids")
+		}
+	}
+
+	return api.App.SubscribeToPresence(conn, userIds)
+}
+
+// unsubscribeStatuses unsubscribes from the given users, or from every user if none are given.
+func (api *API) unsubscribeStatuses(conn *app.WebConn, req *model.WebSocketRequest) (map[string]interface{}, *model.AppError) {
+	api.App.UnsubscribeFromPresence(conn, model.ArrayFromInterface(req.Data["user_ids"]))
+	return nil, nil
+}
@@ -4,11 +4,13 @@
 package wsapi
 
 import (
+	"github.com/mattermost/mattermost-server/app"
 	"github.com/mattermost/mattermost-server/model"
 )
 
 func (api *API) InitUser() {
 	api.Router.Handle("user_typing", api.ApiWebSocketHandler(api.userTyping))
+	api.Router.Handle("view_thread", api.ApiWebSocketConnHandler(api.viewThread))
 }
 
 func (api *API) userTyping(req *model.WebSocketRequest) (map[string]interface{}, *model.AppError) {
@@ -21,15 +23,25 @@ func (api *API) userTyping(req *model.WebSocketRequest) (map[string]interface{},
 	var parentId string
 	if parentId, ok = req.Data["parent_id"].(string); !ok {
 		parentId = ""
+	} else if parentId != "" && len(parentId) != 26 {
+		return nil, NewInvalidWebSocketParamError(req.Action, "parent_id")
 	}
 
-	omitUsers := make(map[string]bool, 1)
-	omitUsers[req.Session.UserId] = true
+	if err := api.App.PublishUserTyping(req.Session.UserId, channelId, parentId); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// viewThread records which thread is open on the connection, or that none is if the thread id is empty.
+func (api *API) viewThread(conn *app.WebConn, req *model.WebSocketRequest) (map[string]interface{}, *model.AppError) {
+	threadId, _ := req.Data["thread_id"].(string)
+	if threadId != "" && len(threadId) != 26 {
+		return nil, NewInvalidWebSocketParamError(req.Action, "thread_id")
+	}
 
-	event := model.NewWebSocketEvent(model.WEBSOCKET_EVENT_TYPING, "", channelId, "", omitUsers)
-	event.Add("parent_id", parentId)
-	event.Add("user_id", req.Session.UserId)
-	api.App.Publish(event)
+	conn.SetActiveThreadId(threadId)
 
 	return nil, nil
 }
@@ -14,12 +14,20 @@ import (
 )
 
 func (api *API) ApiWebSocketHandler(wh func(*model.WebSocketRequest) (map[string]interface{}, *model.AppError)) webSocketHandler {
+	return webSocketHandler{api.App, func(conn *app.WebConn, r *model.WebSocketRequest) (map[string]interface{}, *model.AppError) {
+		return wh(r)
+	}}
+}
+
+// ApiWebSocketConnHandler is the same as ApiWebSocketHandler for actions that change the connection that the request
+// was sent over.
+func (api *API) ApiWebSocketConnHandler(wh func(*app.WebConn, *model.WebSocketRequest) (map[string]interface{}, *model.AppError)) webSocketHandler {
 	return webSocketHandler{api.App, wh}
 }
 
 type webSocketHandler struct {
 	app         *app.App
-	handlerFunc func(*model.WebSocketRequest) (map[string]interface{}, *model.AppError)
+	handlerFunc func(*app.WebConn, *model.WebSocketRequest) (map[string]interface{}, *model.AppError)
 }
 
 func (wh webSocketHandler) ServeWebSocket(conn *app.WebConn, r *model.WebSocketRequest) {
@@ -42,7 +50,7 @@ func (wh webSocketHandler) ServeWebSocket(conn *app.WebConn, r *model.WebSocketR
 	var data map[string]interface{}
 	var err *model.AppError
 
-	if data, err = wh.handlerFunc(r); err != nil {
+	if data, err = wh.handlerFunc(conn, r); err != nil {
 		mlog.Error(fmt.Sprintf("%v:%v seq=%v uid=%v %v [details: %v]", "websocket", r.Action, r.Seq, r.Session.UserId, err.SystemMessage(utils.T), err.DetailedError))
 		err.DetailedError = ""
 		errResp := model.NewWebSocketError(r.Seq, err)